@@ -0,0 +1,145 @@
+package main
+
+import (
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/domain/summary"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+)
+
+const archiveUsage = `Usage: billing-engine archive [flags]
+
+Lists the loans in the archive tables, or with -run archives the paid-off
+loans past retention.days now, or with -restore moves one archived loan back
+into the live tables and reassigns it to its customer when the customer has
+no loan.
+
+`
+
+type archiveOptions struct {
+	run     bool
+	restore int64
+	limit   int
+}
+
+func parseArchiveFlags(args []string, output io.Writer) (archiveOptions, error) {
+	fs := flag.NewFlagSet("archive", flag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.Usage = func() {
+		fmt.Fprint(output, archiveUsage)
+		fs.PrintDefaults()
+	}
+	run := fs.Bool("run", false, "archive the loans past the retention period")
+	restore := fs.Int64("restore", 0, "internal ID of the archived loan to restore")
+	limit := fs.Int("limit", 100, "maximum number of archived loans to list")
+	if err := fs.Parse(args); err != nil {
+		return archiveOptions{}, err
+	}
+	if *run && *restore != 0 {
+		return archiveOptions{}, errors.New("-run and -restore cannot be combined")
+	}
+	if *restore < 0 {
+		return archiveOptions{}, errors.New("-restore must be a positive loan ID")
+	}
+	return archiveOptions{run: *run, restore: *restore, limit: *limit}, nil
+}
+
+// runArchive implements the archive subcommand and returns the exit code.
+// Like replay it reads the service's configuration for the database and the
+// retention policy.
+func runArchive(args []string) int {
+	opts, err := parseArchiveFlags(args, os.Stderr)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	cfg, logger, _ := initializeApp()
+	policy, err := loan.NewArchivePolicy(cfg.Retention.Days, cfg.Retention.BatchSize)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "invalid retention configuration:", err)
+		return 1
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	repos := initializeDatabase(cfg, clock.System(), logger)
+	defer closeDatabase(repos, logger)
+	archives := loan.NewArchiveService(repos.Archive, policy, clock.System(), logger)
+	summaries := summary.NewService(repos.Summaries, logger)
+
+	switch {
+	case opts.run:
+		report, err := archives.ArchiveCompleted(ctx)
+		if report != nil {
+			fmt.Fprintf(os.Stdout, "cutoff %s, archived %d, skipped %d\n", report.Cutoff.UTC().Format(time.RFC3339), len(report.Archived), report.Skipped)
+			for _, a := range report.Archived {
+				refreshSummary(ctx, summaries, a.CustomerID)
+			}
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	case opts.restore != 0:
+		restored, relinked, err := archives.Restore(ctx, opts.restore)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Fprintf(os.Stdout, "restored loan %d (%s)\n", restored.LoanID, restored.PublicID)
+		switch {
+		case relinked:
+			fmt.Fprintf(os.Stdout, "reassigned to customer %d\n", *restored.CustomerID)
+			refreshSummary(ctx, summaries, restored.CustomerID)
+		case restored.CustomerID != nil:
+			fmt.Fprintf(os.Stdout, "customer %d has another loan or no longer exists, the loan is unassigned\n", *restored.CustomerID)
+		}
+	default:
+		archived, err := archives.ListArchived(ctx, opts.limit)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		printArchivedLoans(os.Stdout, archived)
+	}
+	return 0
+}
+
+// refreshSummary reports a failed refresh without failing the command; the
+// nightly summary rebuild corrects it.
+func refreshSummary(ctx context.Context, summaries summary.Service, customerID *int64) {
+	if customerID == nil {
+		return
+	}
+	if err := summaries.Refresh(ctx, *customerID); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to refresh the summary of customer %d: %v\n", *customerID, err)
+	}
+}
+
+func printArchivedLoans(w io.Writer, archived []loan.ArchivedLoan) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "LOAN ID\tPUBLIC ID\tCUSTOMER\tCOMPLETED AT\tARCHIVED AT")
+	for _, a := range archived {
+		customerID := "-"
+		if a.CustomerID != nil {
+			customerID = fmt.Sprint(*a.CustomerID)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", a.LoanID, a.PublicID, customerID,
+			a.CompletedAt.UTC().Format(time.RFC3339), a.ArchivedAt.UTC().Format(time.RFC3339))
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "%d archived loans\n", len(archived))
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"billing-engine/internal/domain/loan"
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseArchiveFlags(t *testing.T) {
+	opts, err := parseArchiveFlags(nil, io.Discard)
+	require.NoError(t, err)
+	assert.Equal(t, archiveOptions{limit: 100}, opts, "lists by default")
+
+	opts, err = parseArchiveFlags([]string{"-restore", "42"}, io.Discard)
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), opts.restore)
+
+	_, err = parseArchiveFlags([]string{"-run", "-restore", "42"}, io.Discard)
+	assert.Error(t, err)
+	_, err = parseArchiveFlags([]string{"-restore", "-1"}, io.Discard)
+	assert.Error(t, err)
+}
+
+func TestPrintArchivedLoans(t *testing.T) {
+	var out bytes.Buffer
+	customerID := int64(9)
+	printArchivedLoans(&out, []loan.ArchivedLoan{{LoanID: 3, PublicID: uuid.New(), CustomerID: &customerID,
+		CompletedAt: time.Date(2022, 5, 2, 0, 0, 0, 0, time.UTC), ArchivedAt: time.Date(2025, 1, 5, 4, 0, 0, 0, time.UTC)}})
+
+	assert.Contains(t, out.String(), "2022-05-02T00:00:00Z")
+	assert.Contains(t, out.String(), "1 archived loans")
+}
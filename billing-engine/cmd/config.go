@@ -0,0 +1,129 @@
+package main
+
+import (
+	"billing-engine/internal/config"
+	"billing-engine/internal/domain/document"
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/infrastructure/errorreport"
+	"billing-engine/internal/infrastructure/esign"
+	"billing-engine/internal/infrastructure/topology"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/viper"
+)
+
+const configUsage = `Usage: billing-engine config validate [flags]
+
+Reads the configuration the service would start with, config.yml in -dir
+under the environment, and lists everything wrong with it: unknown keys,
+durations without a unit, values out of range and policies the service
+would refuse. Nothing is connected to. Exits with 1 when there is a problem.
+
+`
+
+type configOptions struct {
+	dir string
+}
+
+func parseConfigFlags(args []string, output io.Writer) (configOptions, error) {
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.Usage = func() {
+		fmt.Fprint(output, configUsage)
+		fs.PrintDefaults()
+	}
+	if len(args) == 0 || args[0] != "validate" {
+		if len(args) > 0 && (args[0] == "-h" || args[0] == "-help" || args[0] == "--help") {
+			fs.Usage()
+			return configOptions{}, flag.ErrHelp
+		}
+		return configOptions{}, errors.New("usage: billing-engine config validate [flags]")
+	}
+	dir := fs.String("dir", ".", "directory holding config.yml")
+	if err := fs.Parse(args[1:]); err != nil {
+		return configOptions{}, err
+	}
+	if fs.NArg() > 0 {
+		return configOptions{}, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+	return configOptions{dir: *dir}, nil
+}
+
+// runConfig implements the config subcommand and returns the exit code.
+func runConfig(args []string) int {
+	opts, err := parseConfigFlags(args, os.Stderr)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	return validateConfig(opts.dir, os.Stdout, os.Stderr)
+}
+
+// validateConfig loads the configuration in dir and writes either where it
+// came from to stdout or all its problems to stderr.
+func validateConfig(dir string, stdout, stderr io.Writer) int {
+	if _, err := loadValidConfig(dir); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	source := viper.ConfigFileUsed()
+	if source == "" {
+		source = "defaults and environment"
+	}
+	fmt.Fprintf(stdout, "configuration is valid, loaded from %s\n", source)
+	return 0
+}
+
+// loadValidConfig loads the configuration in dir and checks the policies
+// the service builds from it at startup, which the config package knows
+// nothing of. Their problems are added to those LoadConfig finds.
+func loadValidConfig(dir string) (*config.Config, error) {
+	cfg, err := config.LoadConfig(dir)
+	if err != nil {
+		return nil, err
+	}
+	if problems := policyProblems(cfg); len(problems) > 0 {
+		return nil, &config.ValidationError{Problems: problems}
+	}
+	return cfg, nil
+}
+
+func policyProblems(cfg *config.Config) []config.Problem {
+	var problems []config.Problem
+	check := func(key string, err error) {
+		if err != nil {
+			problems = append(problems, config.Problem{Key: key, Message: err.Error()})
+		}
+	}
+	payments, err := paymentPolicy(cfg.Payments)
+	check("payments", err)
+	if err == nil {
+		_, err = directDebitConfig(cfg, payments)
+		check("directDebit", err)
+	}
+	_, err = loan.NewDelinquencyPolicy(cfg.Delinquency.MissedPayments, cfg.Delinquency.CurePayments, cfg.Delinquency.GraceDays, cfg.Delinquency.LateFee)
+	check("delinquency", err)
+	_, err = taxPolicy(cfg.Tax)
+	check("tax", err)
+	_, err = loan.NewCreditPolicy(cfg.Credit.Limits)
+	check("credit", err)
+	_, err = productCatalog(cfg.Products)
+	check("products", err)
+	_, err = loan.NewArchivePolicy(cfg.Retention.Days, cfg.Retention.BatchSize)
+	check("retention", err)
+	_, err = document.NewRetentionPolicy(cfg.Documents.Retention)
+	check("documents.retention", err)
+	check("rabbitmq.topology", topology.Validate(cfg.RabbitMQ.Topology))
+	_, err = errorreport.New(cfg.ErrorReporting, nil)
+	check("errorReporting", err)
+	_, err = esign.New(cfg.ESign, nil)
+	check("esign", err)
+	return problems
+}
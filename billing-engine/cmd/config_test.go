@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConfigFlags(t *testing.T) {
+	opts, err := parseConfigFlags([]string{"validate"}, io.Discard)
+	require.NoError(t, err)
+	assert.Equal(t, ".", opts.dir)
+
+	opts, err = parseConfigFlags([]string{"validate", "-dir", "/etc/billing-engine"}, io.Discard)
+	require.NoError(t, err)
+	assert.Equal(t, "/etc/billing-engine", opts.dir)
+
+	_, err = parseConfigFlags([]string{"-h"}, io.Discard)
+	assert.ErrorIs(t, err, flag.ErrHelp)
+	for _, args := range [][]string{nil, {"show"}, {"validate", "extra"}} {
+		_, err := parseConfigFlags(args, io.Discard)
+		assert.Error(t, err, args)
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	write := func(t *testing.T, content string) string {
+		viper.Reset()
+		t.Cleanup(viper.Reset)
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "config.yml"), []byte(content), 0o644))
+		return dir
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		dir := write(t, "server:\n  port: 8081\n")
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, 0, validateConfig(dir, &stdout, &stderr))
+		assert.Equal(t, "configuration is valid, loaded from "+filepath.Join(dir, "config.yml")+"\n", stdout.String())
+		assert.Empty(t, stderr.String())
+	})
+
+	t.Run("policies are only checked once the config loads", func(t *testing.T) {
+		dir := write(t, "batch:\n  delinquencyTimout: 30\ndelinquency:\n  missedPayments: 0\n")
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, 1, validateConfig(dir, &stdout, &stderr))
+		assert.Empty(t, stdout.String())
+		assert.Contains(t, stderr.String(), "batch.delinquencytimout: unknown setting, did you mean batch.delinquencyTimeout?")
+		assert.NotContains(t, stderr.String(), "\n  delinquency:")
+	})
+
+	t.Run("policy problems", func(t *testing.T) {
+		dir := write(t, "delinquency:\n  missedPayments: 0\npayments:\n  currency: EUR\n")
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, 1, validateConfig(dir, &stdout, &stderr))
+		assert.Contains(t, stderr.String(), "invalid configuration, 2 problems:\n  payments: ")
+		assert.Contains(t, stderr.String(), "\n  delinquency: ")
+	})
+
+	t.Run("an e-signature provider without a callback secret", func(t *testing.T) {
+		dir := write(t, "esign:\n  provider: http\n  url: http://esign:8080\n")
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, 1, validateConfig(dir, &stdout, &stderr))
+		assert.Contains(t, stderr.String(), "esign: e-signature callback secret is empty")
+	})
+}
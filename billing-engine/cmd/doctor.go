@@ -0,0 +1,347 @@
+package main
+
+import (
+	"billing-engine/internal/config"
+	"billing-engine/internal/infrastructure/database"
+	"billing-engine/internal/infrastructure/database/postgres"
+	"billing-engine/internal/infrastructure/redis"
+	"billing-engine/migrations"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/spf13/viper"
+)
+
+const doctorUsage = `Usage: billing-engine doctor [flags]
+
+Checks what the service needs to start, without starting it: the
+configuration, Postgres and its schema, Redis and RabbitMQ with the
+exchanges and queues of rabbitmq.topology. Nothing is created or changed.
+Exits with 1 when a check fails.
+
+`
+
+type doctorOptions struct {
+	timeout time.Duration
+	color   bool
+}
+
+func parseDoctorFlags(args []string, output io.Writer) (doctorOptions, error) {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.Usage = func() {
+		fmt.Fprint(output, doctorUsage)
+		fs.PrintDefaults()
+	}
+	timeout := fs.Duration("timeout", 5*time.Second, "how long each check may take")
+	noColor := fs.Bool("no-color", false, "print the report without colors")
+	if err := fs.Parse(args); err != nil {
+		return doctorOptions{}, err
+	}
+	if *timeout <= 0 {
+		return doctorOptions{}, errors.New("-timeout must be positive")
+	}
+	return doctorOptions{timeout: *timeout, color: !*noColor}, nil
+}
+
+// runDoctor implements the doctor subcommand and returns the exit code.
+// Unlike the other subcommands it does not stop at a configuration error,
+// and it connects once without the retries the service makes, so a
+// dependency that is down shows up at once.
+func runDoctor(args []string) int {
+	opts, err := parseDoctorFlags(args, os.Stderr)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	d := &doctor{}
+	defer d.close()
+	results := runDoctorChecks(ctx, d.checks(), opts.timeout)
+	if !printDoctorReport(os.Stdout, results, opts.color && isTerminal(os.Stdout)) {
+		return 1
+	}
+	return 0
+}
+
+// errSkipped marks a check that did not run, because it is not configured
+// or depends on one that failed.
+var errSkipped = errors.New("skipped")
+
+func skip(format string, args ...any) error {
+	return fmt.Errorf("%w: "+format, append([]any{errSkipped}, args...)...)
+}
+
+type doctorCheck struct {
+	name string
+	run  func(ctx context.Context) (string, error)
+}
+
+type doctorResult struct {
+	name   string
+	detail string
+	err    error
+}
+
+func (r doctorResult) status() string {
+	switch {
+	case r.err == nil:
+		return "OK"
+	case errors.Is(r.err, errSkipped):
+		return "SKIP"
+	}
+	return "FAIL"
+}
+
+// runDoctorChecks runs checks in order, each with its own timeout.
+func runDoctorChecks(ctx context.Context, checks []doctorCheck, timeout time.Duration) []doctorResult {
+	results := make([]doctorResult, 0, len(checks))
+	for _, c := range checks {
+		checkCtx, cancel := context.WithTimeout(ctx, timeout)
+		detail, err := c.run(checkCtx)
+		cancel()
+		results = append(results, doctorResult{name: c.name, detail: detail, err: err})
+	}
+	return results
+}
+
+const (
+	colorReset = "\033[0m"
+	colorRed   = "\033[31m"
+	colorGreen = "\033[32m"
+	colorGrey  = "\033[90m"
+)
+
+// printDoctorReport writes one line per check and a summary, and reports
+// whether none failed.
+func printDoctorReport(w io.Writer, results []doctorResult, color bool) bool {
+	width := 0
+	for _, r := range results {
+		width = max(width, len(r.name))
+	}
+	failed := 0
+	for _, r := range results {
+		status := r.status()
+		detail := r.detail
+		if r.err != nil {
+			detail = strings.TrimPrefix(r.err.Error(), errSkipped.Error()+": ")
+		}
+		if status == "FAIL" {
+			failed++
+		}
+		if color {
+			status = map[string]string{"OK": colorGreen, "FAIL": colorRed, "SKIP": colorGrey}[status] + fmt.Sprintf("%-4s", status) + colorReset
+		}
+		fmt.Fprintf(w, "%-4s  %-*s  %s\n", status, width, r.name, detail)
+	}
+	if failed > 0 {
+		fmt.Fprintf(w, "%d of %d checks failed\n", failed, len(results))
+		return false
+	}
+	fmt.Fprintf(w, "all %d checks passed\n", len(results))
+	return true
+}
+
+func isTerminal(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// doctor holds what the checks share: the configuration, and the
+// connections later checks reuse.
+type doctor struct {
+	cfg    *config.Config
+	db     *pgx.Conn
+	broker *amqp.Connection
+}
+
+func (d *doctor) checks() []doctorCheck {
+	return []doctorCheck{
+		{name: "config", run: d.checkConfig},
+		{name: "postgres", run: d.checkPostgres},
+		{name: "migrations", run: d.checkMigrations},
+		{name: "redis", run: d.checkRedis},
+		{name: "rabbitmq", run: d.checkRabbitMQ},
+		{name: "topology", run: d.checkTopology},
+	}
+}
+
+func (d *doctor) close() {
+	if d.db != nil {
+		_ = d.db.Close(context.Background())
+	}
+	if d.broker != nil {
+		_ = d.broker.Close()
+	}
+}
+
+// checkConfig loads the configuration as config validate does.
+func (d *doctor) checkConfig(context.Context) (string, error) {
+	cfg, err := loadValidConfig(".")
+	if err != nil {
+		return "", err
+	}
+	d.cfg = cfg
+	source := viper.ConfigFileUsed()
+	if source == "" {
+		source = "defaults and environment"
+	}
+	return "loaded from " + source, nil
+}
+
+func (d *doctor) checkPostgres(ctx context.Context) (string, error) {
+	if d.cfg == nil {
+		return "", skip("invalid configuration")
+	}
+	if d.cfg.Database.Driver == database.DriverSQLite {
+		return "", skip("driver is sqlite, %s", d.cfg.Database.Path)
+	}
+	if d.cfg.Database.URL == "" {
+		return "", errors.New("database URL is empty in configuration")
+	}
+	conn, err := pgx.Connect(ctx, d.cfg.Database.URL)
+	if err != nil {
+		return "", err
+	}
+	d.db = conn
+	var version string
+	if err := conn.QueryRow(ctx, "SHOW server_version").Scan(&version); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("connected to %s on %s, server %s", conn.Config().Database, conn.Config().Host, version), nil
+}
+
+// checkMigrations compares the schema with the migrations built into the
+// binary. The sqlite schema is applied whenever the database is opened.
+func (d *doctor) checkMigrations(ctx context.Context) (string, error) {
+	if d.db == nil {
+		return "", skip("no Postgres connection")
+	}
+	ms, err := migrations.Embedded()
+	if err != nil {
+		return "", err
+	}
+	status, err := postgres.CheckSchema(ctx, d.db, ms)
+	if err != nil {
+		return "", err
+	}
+	if len(status.Pending) > 0 {
+		missing := make([]string, len(status.Missing))
+		for i, object := range status.Missing {
+			missing[i] = object.String()
+		}
+		return "", fmt.Errorf("%d not applied, from %s (missing %s)", len(status.Pending), status.Pending[0], strings.Join(missing, ", "))
+	}
+	return "up to date at " + status.Current, nil
+}
+
+func (d *doctor) checkRedis(ctx context.Context) (string, error) {
+	if d.cfg == nil {
+		return "", skip("invalid configuration")
+	}
+	if d.cfg.Redis.Addr == "" {
+		return "", skip("not configured, state is kept per instance")
+	}
+	client, err := redis.NewClient(d.cfg.Redis)
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+	if _, err := client.Do(ctx, "PING"); err != nil {
+		return "", err
+	}
+	return "connected to " + d.cfg.Redis.Addr, nil
+}
+
+func (d *doctor) checkRabbitMQ(ctx context.Context) (string, error) {
+	if d.cfg == nil {
+		return "", skip("invalid configuration")
+	}
+	uri, err := rabbitMQURI(d.cfg.RabbitMQ)
+	if err != nil {
+		return "", err
+	}
+	timeout := 5 * time.Second
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+	conn, err := amqp.DialConfig(uri, amqp.Config{Dial: amqp.DefaultDial(timeout)})
+	if err != nil {
+		return "", err
+	}
+	d.broker = conn
+	return fmt.Sprintf("connected to %s:%d", d.cfg.RabbitMQ.Host, d.cfg.RabbitMQ.Port), nil
+}
+
+// checkTopology looks up every exchange and queue of the topology, and the
+// exchange events are published to, without declaring them. A passive declare that fails closes its channel, so each
+// lookup gets a channel of its own.
+func (d *doctor) checkTopology(context.Context) (string, error) {
+	if d.broker == nil {
+		return "", skip("no RabbitMQ connection")
+	}
+	var missing []string
+	lookup := func(kind, name string, declare func(*amqp.Channel) error) error {
+		ch, err := d.broker.Channel()
+		if err != nil {
+			return err
+		}
+		defer ch.Close()
+		if err := declare(ch); err != nil {
+			var amqpErr *amqp.Error
+			if !errors.As(err, &amqpErr) || amqpErr.Code != amqp.NotFound {
+				return fmt.Errorf("%s %s: %w", kind, name, err)
+			}
+			missing = append(missing, kind+" "+name)
+		}
+		return nil
+	}
+	top := d.cfg.RabbitMQ.Topology
+	if !slices.ContainsFunc(top.Exchanges, func(e config.ExchangeConfig) bool { return e.Name == d.cfg.RabbitMQ.ExchangeName }) {
+		top.Exchanges = append(slices.Clone(top.Exchanges), config.ExchangeConfig{Name: d.cfg.RabbitMQ.ExchangeName})
+	}
+	for _, e := range top.Exchanges {
+		kind := e.Type
+		if kind == "" {
+			kind = amqp.ExchangeTopic
+		}
+		err := lookup("exchange", e.Name, func(ch *amqp.Channel) error {
+			return ch.ExchangeDeclarePassive(e.Name, kind, true, false, false, false, nil)
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+	for _, q := range top.Queues {
+		err := lookup("queue", q.Name, func(ch *amqp.Channel) error {
+			_, err := ch.QueueDeclarePassive(q.Name, true, false, false, false, nil)
+			return err
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("missing %s", strings.Join(missing, ", "))
+	}
+	return fmt.Sprintf("%d exchanges and %d queues exist", len(top.Exchanges), len(top.Queues)), nil
+}
@@ -5,24 +5,54 @@ import (
 	"billing-engine/internal/api"
 	"billing-engine/internal/batch"
 	"billing-engine/internal/config"
+	"billing-engine/internal/domain/agreement"
+	"billing-engine/internal/domain/collections"
+	"billing-engine/internal/domain/contact"
 	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/domain/directdebit"
+	"billing-engine/internal/domain/document"
+	"billing-engine/internal/domain/integrity"
 	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/domain/note"
+	"billing-engine/internal/domain/overview"
+	"billing-engine/internal/domain/summary"
+	"billing-engine/internal/domain/tenant"
 	"billing-engine/internal/event"
-	"billing-engine/internal/infrastructure/database/postgres"
+	"billing-engine/internal/infrastructure/database"
+	"billing-engine/internal/infrastructure/errorreport"
+	"billing-engine/internal/infrastructure/esign"
 	"billing-engine/internal/infrastructure/logging"
+	"billing-engine/internal/infrastructure/monitoring"
+	"billing-engine/internal/infrastructure/notify"
+	"billing-engine/internal/infrastructure/redis"
+	"billing-engine/internal/infrastructure/storage"
+	"billing-engine/internal/infrastructure/tlsconfig"
+	"billing-engine/internal/infrastructure/topology"
+	"billing-engine/internal/jobs"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/buildinfo"
+	"billing-engine/internal/pkg/clock"
+	"billing-engine/internal/pkg/retry"
+	"billing-engine/internal/ratelimit"
+	"billing-engine/internal/sandbox"
 	"context"
 	"errors"
 	"fmt"
+	"iter"
 	"log/slog"
+	"maps"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
+	"strings"
 	"syscall"
 	"time"
+	// Batch job timezones resolve in images without a zoneinfo database.
+	_ "time/tzdata"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 
-	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/robfig/cron/v3"
 	"github.com/spf13/viper"
 )
@@ -39,62 +69,443 @@ import (
 // @license.name MIT
 // @license.url https://opensource.org/licenses/MIT
 
+// @BasePath /v1
+
 // @securityDefinitions.apikey BearerAuth
 // @in header
 // @name Authorization
 func main() {
-	cfg, logger := initializeApp()
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		os.Exit(runReplay(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "archive" {
+		os.Exit(runArchive(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "doctor" {
+		os.Exit(runDoctor(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		os.Exit(runConfig(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "seed" {
+		os.Exit(runSeed(os.Args[2:]))
+	}
 
-	dbPool := initializeDatabase(cfg, logger)
-	defer closeDatabase(dbPool, logger)
+	cfg, logger, reporter := initializeApp()
+	clk, billingClock := setupClock(cfg, logger)
+
+	repos := initializeDatabase(cfg, clk, logger)
+	defer closeDatabase(repos, logger)
+	if err := topology.Validate(cfg.RabbitMQ.Topology); err != nil {
+		logger.Error("Invalid RabbitMQ topology configuration", "error", err)
+		os.Exit(1)
+	}
 	rabbitMQConn, _ := setupRabbitMQ(cfg, logger)
-	loanService, customerService, loanRepo := initializeServices(rabbitMQConn, dbPool, logger)
+	eventHub := event.NewHub(cfg.Events.BufferSize, cfg.Events.ReplaySize, logger)
+	payments, err := paymentPolicy(cfg.Payments)
+	if err != nil {
+		logger.Error("Invalid payment configuration", "error", err)
+		os.Exit(1)
+	}
+	delinquency, err := loan.NewDelinquencyPolicy(cfg.Delinquency.MissedPayments, cfg.Delinquency.CurePayments, cfg.Delinquency.GraceDays, cfg.Delinquency.LateFee)
+	if err != nil {
+		logger.Error("Invalid delinquency configuration", "error", err)
+		os.Exit(1)
+	}
+	// Tenant settings are cached by the wall clock, not the billing clock,
+	// which a sandbox moves by whole days.
+	tenantService := tenant.NewService(repos.Tenants, tenant.Defaults{
+		Delinquency: delinquency, RateLimitRPS: cfg.Server.RateLimit.RPS, RateLimitBurst: cfg.Server.RateLimit.Burst,
+		ReminderWindowDays: cfg.Collections.ReminderWindowDays,
+	}, cfg.Cache.TenantSettingsTTL, nil, logger)
+	delinquencySource := tenant.DelinquencySource{Service: tenantService}
+	taxes, err := taxPolicy(cfg.Tax)
+	if err != nil {
+		logger.Error("Invalid tax configuration", "error", err)
+		os.Exit(1)
+	}
+	credit, err := loan.NewCreditPolicy(cfg.Credit.Limits)
+	if err != nil {
+		logger.Error("Invalid credit configuration", "error", err)
+		os.Exit(1)
+	}
+	products, err := productCatalog(cfg.Products)
+	if err != nil {
+		logger.Error("Invalid products configuration", "error", err)
+		os.Exit(1)
+	}
+	duplicates, err := customer.NewDuplicatePolicy(cfg.Customers.DuplicateCheck)
+	if err != nil {
+		logger.Error("Invalid customers configuration", "error", err)
+		os.Exit(1)
+	}
+	archivePolicy, err := loan.NewArchivePolicy(cfg.Retention.Days, cfg.Retention.BatchSize)
+	if err != nil {
+		logger.Error("Invalid retention configuration", "error", err)
+		os.Exit(1)
+	}
+	loanService, customerService, contactService, replayService, eventBuffer := initializeServices(rabbitMQConn, cfg.RabbitMQ.ExchangeName, repos, eventHub, cfg.Events, payments, delinquencySource, taxes, credit, products, duplicates, clk, logger)
+	eventBuffer.Start()
+	loanService = setupOutstandingCache(cfg, loanService, clk, logger)
+	importService := customer.NewImportService(repos.Customers, eventBuffer, cfg.Import.ChunkSize, clk, logger)
+	var attachments note.ObjectStore
+	var documentStore document.ObjectStore
+	if store := setupObjectStore(cfg, logger); store != nil {
+		attachments, documentStore = store, store
+	}
+	noteService := note.NewService(repos.Notes, attachments, logger)
 
-	updateJob := batch.NewUpdateDelinquencyJob(loanRepo, loanService, customerService, logger)
+	snapshotService := loan.NewSnapshotService(repos.Snapshots, repos.Loans, logger)
+	ddConfig, err := directDebitConfig(cfg, payments)
+	if err != nil {
+		logger.Error("Invalid direct-debit configuration", "error", err)
+		os.Exit(1)
+	}
+	directDebitService := directdebit.NewService(repos.DirectDebits, loanService, ddConfig, clk, logger)
+	collectionsService := collections.NewService(repos.Collections, eventBuffer, tenant.ReminderWindowSource{Service: tenantService}, clk, logger)
+	docConfig, err := documentConfig(cfg, payments)
+	if err != nil {
+		logger.Error("Invalid documents configuration", "error", err)
+		os.Exit(1)
+	}
+	documentService := document.NewService(repos.Documents, documentStore, eventBuffer, docConfig, clk, logger)
+	agreementService := agreement.NewService(repos.Agreements, loanService, customerService, contactService, documentService, setupESignProvider(cfg, logger), clk, logger)
+	summaryService := summary.NewService(repos.Summaries, logger)
+	overviewService := overview.NewService(customerService, loanService, collectionsService, setupNotificationSource(cfg, logger), logger)
+	integrityService := integrity.NewService(repos.Integrity, clk, logger)
+	archiveService := loan.NewArchiveService(repos.Archive, archivePolicy, clk, logger)
+	summaryProjector := summary.NewProjector(summaryService, eventHub, logger)
+	summaryProjector.Start(context.Background())
+	defer summaryProjector.Stop()
+	backlogMonitor := event.NewBacklogMonitor(repos.Events, cfg.Events.BacklogCheckInterval, logger)
+	backlogMonitor.Start(context.Background())
+	defer backlogMonitor.Stop()
+	accessList := setupAccessList(cfg, logger)
+	accessList.Start(context.Background())
+	defer accessList.Stop()
+	jobRunner := jobs.NewRunner(repos.Jobs, jobs.Config{
+		Workers: cfg.Jobs.Workers, QueueSize: cfg.Jobs.QueueSize, Retention: cfg.Jobs.Retention,
+		PollInterval: cfg.Jobs.PollInterval, Lease: cfg.Jobs.Lease, MaxAttempts: cfg.Jobs.MaxAttempts,
+	}, nil, logger)
+	runRecorder := jobs.NewRunRecorder(repos.JobRuns, nil, logger)
+	watchdog := setupWatchdog(cfg, runRecorder, logger)
+	overlapGuard := jobs.NewOverlapGuard(repos.JobLocks, cfg.Jobs.Lease, nil, logger)
+
+	updateJob := batch.NewUpdateDelinquencyJob(repos.Loans, loanService, customerService, delinquencySource, clk, logger)
+	snapshotJob := batch.NewLoanSnapshotJob(snapshotService, clk, logger)
+	var directDebitJob *batch.DirectDebitJob
+	if cfg.DirectDebit.Enabled {
+		directDebitJob = batch.NewDirectDebitJob(directDebitService, cfg.DirectDebit.ExportDir, clk, logger)
+	}
+	collectionsJob := batch.NewCollectionsAssignmentJob(collectionsService, logger)
+	reminderJob := batch.NewReminderEscalationJob(collectionsService, logger)
+	summaryJob := batch.NewSummaryRebuildJob(summaryService, logger)
+	integrityJob := batch.NewIntegrityCheckJob(integrityService, logger)
+	partitionJob := batch.NewPartitionMaintenanceJob(repos.Partitions, cfg.Batch.PartitionMonthsAhead, clk, logger)
+	var archiveJob *batch.LoanArchiveJob
+	if cfg.Retention.Enabled {
+		archiveJob = batch.NewLoanArchiveJob(archiveService, summaryService, logger)
+	}
+	var certificateJob *batch.PayoffCertificateJob
+	var documentRetentionJob *batch.DocumentRetentionJob
+	if documentStore != nil {
+		certificateJob = batch.NewPayoffCertificateJob(documentService, logger)
+		documentRetentionJob = batch.NewDocumentRetentionJob(documentService, logger)
+	}
+	sandboxService := setupSandbox(billingClock, updateJob, snapshotJob, collectionsJob, reminderJob, summaryJob, logger)
 
-	cronScheduler := startBatchJobs(cfg, logger, updateJob)
-	router := api.SetupRouter(loanService, customerService, cfg, logger)
+	cronScheduler, err := startBatchJobs(cfg, jobRunner, runRecorder, watchdog, overlapGuard, logger, updateJob, snapshotJob, collectionsJob, reminderJob, summaryJob, integrityJob, partitionJob, directDebitJob, archiveJob, certificateJob, documentRetentionJob)
+	if err != nil {
+		logger.Error("Invalid batch job schedule", "error", err)
+		os.Exit(1)
+	}
+	if watchdog != nil {
+		watchdog.Start(context.Background())
+		defer watchdog.Stop()
+	}
+	router := api.SetupRouter(loanService, customerService, importService, noteService, documentService, agreementService, snapshotService, directDebitService, contactService, collectionsService, summaryService, overviewService, integrityService, tenantService, eventHub, replayService, clk, sandboxService, accessList, jobRunner, runRecorder, reporter, cfg, logger)
+	jobRunner.Start(context.Background())
 
 	srv, serverErrors, shutdownChan := startServer(cfg, router, logger)
-	handleShutdown(srv, cronScheduler, rabbitMQConn, shutdownChan, serverErrors, logger)
+	handleShutdown(srv, cronScheduler, jobRunner, eventBuffer, rabbitMQConn, shutdownChan, serverErrors, logger)
 }
 
-func initializeApp() (*config.Config, *slog.Logger) {
-	cfg, err := config.LoadConfig(".")
+// initializeApp also returns the error reporter, nil when none is
+// configured, which the logger already reports error records to.
+func initializeApp() (*config.Config, *slog.Logger, errorreport.Reporter) {
+	cfg, err := loadValidConfig(".")
 	if err != nil {
-		slog.Error("Failed to load configuration", "error", err)
+		var invalid *config.ValidationError
+		if errors.As(err, &invalid) {
+			// Printed as it is, one problem per line, rather than squeezed
+			// into a log attribute.
+			fmt.Fprintln(os.Stderr, err)
+		} else {
+			slog.Error("Failed to load configuration", "error", err)
+		}
 		os.Exit(1)
 	}
 
-	logger := setupLogger(cfg.Logger)
+	reporter, reporterErr := errorreport.New(cfg.ErrorReporting, nil)
+	logger := setupLogger(cfg.Logger, reporter)
 	slog.SetDefault(logger)
-	logger.Info("Application starting...", "config_source", viper.ConfigFileUsed())
+	build := buildinfo.Get()
+	logger.Info("Application starting...", "config_source", viper.ConfigFileUsed(),
+		"version", build.Version, "commit", build.Commit, "build_date", build.Date, "go_version", build.GoVersion)
+	switch {
+	case reporterErr != nil:
+		logger.Error("Failed to configure error reporting, errors are only logged", "error", reporterErr)
+	case reporter != nil:
+		logger.Info("Reporting errors and panics to an error tracker", "environment", cfg.ErrorReporting.Environment)
+	}
 
-	return cfg, logger
+	return cfg, logger, reporter
+}
+
+// setupClock returns the clock everything is built on. In sandbox mode that
+// is an offset clock, returned a second time so the sandbox can move it.
+func setupClock(cfg *config.Config, logger *slog.Logger) (clock.Clock, *clock.Offset) {
+	if !cfg.Sandbox.Enabled {
+		return clock.System(), nil
+	}
+	logger.Warn("Sandbox mode is enabled: the billing clock can be advanced through /admin/sandbox. Do not run this configuration in production.")
+	billingClock := clock.NewOffset(clock.System())
+	return billingClock, billingClock
+}
+
+func setupSandbox(billingClock *clock.Offset, updateJob *batch.UpdateDelinquencyJob, snapshotJob *batch.LoanSnapshotJob, collectionsJob *batch.CollectionsAssignmentJob, reminderJob *batch.ReminderEscalationJob, summaryJob *batch.SummaryRebuildJob, logger *slog.Logger) sandbox.Service {
+	if billingClock == nil {
+		return nil
+	}
+	return sandbox.NewService(billingClock, []sandbox.Job{
+		{Name: "delinquency", Run: updateJob.Run},
+		{Name: "snapshot", Run: snapshotJob.Run},
+		{Name: "collections", Run: collectionsJob.Run},
+		{Name: "reminders", Run: reminderJob.Run},
+		{Name: "summary", Run: summaryJob.Run},
+	}, logger)
 }
 
-func initializeDatabase(cfg *config.Config, logger *slog.Logger) *pgxpool.Pool {
-	logger.Info("Initializing database connection pool...")
-	dbPool, err := postgres.NewConnectionPool(context.Background(), cfg.Database, logger)
+// setupWatchdog returns the batch job watchdog, nil when
+// batch.watchdog.enabled is off. It checks against the wall clock, as the
+// cron schedules are.
+func setupWatchdog(cfg *config.Config, recorder *jobs.RunRecorder, logger *slog.Logger) *jobs.Watchdog {
+	if !cfg.Batch.Watchdog.Enabled {
+		logger.Warn("Batch job watchdog is disabled: missed and overrunning runs raise no alerts.")
+		return nil
+	}
+	return jobs.NewWatchdog(recorder, jobs.WatchdogConfig{
+		Interval: cfg.Batch.Watchdog.Interval, Grace: cfg.Batch.Watchdog.Grace, CancelOverruns: cfg.Batch.Watchdog.CancelOverruns,
+	}, nil, logger)
+}
+
+func initializeDatabase(cfg *config.Config, clk clock.Clock, logger *slog.Logger) *database.Repositories {
+	logger.Info("Initializing database connection pool...", "driver", cfg.Database.Driver)
+	repos, err := database.Open(context.Background(), cfg.Database, clk, logger)
 	if err != nil {
 		logger.Error("Failed to initialize database connection pool", "error", err)
 		os.Exit(1)
 	}
-	return dbPool
+	return repos
 }
 
-func closeDatabase(dbPool *pgxpool.Pool, logger *slog.Logger) {
+func closeDatabase(repos *database.Repositories, logger *slog.Logger) {
 	logger.Info("Closing database connection pool...")
-	dbPool.Close()
+	repos.Close()
+}
+
+// paymentPolicy resolves the configured currency to its minor unit.
+func paymentPolicy(cfg config.PaymentsConfig) (loan.PaymentPolicy, error) {
+	digits, err := cfg.MinorUnitDigits()
+	if err != nil {
+		return loan.PaymentPolicy{}, err
+	}
+	return loan.NewPaymentPolicy(digits, cfg.Tolerance, cfg.Rounding)
+}
+
+// taxPolicy converts the configured rates, keyed by fee type, to the loan
+// domain's.
+func taxPolicy(cfg config.TaxConfig) (loan.TaxPolicy, error) {
+	rates := make(map[string]loan.TaxRates, len(cfg.Jurisdictions))
+	for name, r := range cfg.Jurisdictions {
+		fees := make(map[loan.FeeType]float64, len(r.Fees))
+		for feeType, rate := range r.Fees {
+			fees[loan.FeeType(feeType)] = rate
+		}
+		rates[name] = loan.TaxRates{Fees: fees, Interest: r.Interest}
+	}
+	return loan.NewTaxPolicy(cfg.Jurisdiction, rates)
+}
+
+func productCatalog(cfg map[string]config.ProductConfig) (loan.ProductCatalog, error) {
+	products := make(map[string]loan.Product, len(cfg))
+	for name, p := range cfg {
+		products[name] = loan.Product{Schedule: loan.ScheduleType(p.Schedule), InterestOnlyWeeks: p.InterestOnlyWeeks, BalloonShare: p.BalloonShare}
+	}
+	return loan.NewProductCatalog(products)
 }
 
-func initializeServices(rabbitConn *amqp.Connection, dbPool *pgxpool.Pool, logger *slog.Logger) (loan.LoanService, customer.CustomerService, loan.Repository) {
+// directDebitConfig formats bank file amounts in the payments currency.
+func directDebitConfig(cfg *config.Config, payments loan.PaymentPolicy) (directdebit.Config, error) {
+	dd := directdebit.Config{
+		Format:   cfg.DirectDebit.Format,
+		LeadDays: cfg.DirectDebit.LeadDays,
+		Creditor: directdebit.Creditor{
+			Name:          cfg.DirectDebit.CreditorName,
+			AccountNumber: cfg.DirectDebit.CreditorAccount,
+			BankCode:      cfg.DirectDebit.CreditorBankCode,
+			SchemeID:      cfg.DirectDebit.CreditorSchemeID,
+		},
+		Currency:        strings.ToUpper(cfg.Payments.Currency),
+		MinorUnitDigits: payments.MinorUnitDigits,
+	}
+	if err := dd.Validate(); err != nil {
+		return directdebit.Config{}, err
+	}
+	if cfg.DirectDebit.Enabled && (dd.Creditor.Name == "" || dd.Creditor.AccountNumber == "" || dd.Creditor.SchemeID == "") {
+		return directdebit.Config{}, fmt.Errorf("direct-debit collection needs the creditor name, account and scheme ID")
+	}
+	return dd, nil
+}
+
+// documentConfig prints certificate dates in the batch timezone and amounts
+// in the payments currency. Uploads are capped like attachments.
+func documentConfig(cfg *config.Config, payments loan.PaymentPolicy) (document.Config, error) {
+	location, err := time.LoadLocation(cfg.Batch.Timezone)
+	if err != nil {
+		return document.Config{}, fmt.Errorf("batch.timezone %q: %w", cfg.Batch.Timezone, err)
+	}
+	retention, err := document.NewRetentionPolicy(cfg.Documents.Retention)
+	if err != nil {
+		return document.Config{}, fmt.Errorf("documents.retention: %w", err)
+	}
+	return document.Config{
+		LinkTTL:         cfg.Documents.LinkTTL,
+		UploadLinkTTL:   cfg.Documents.UploadLinkTTL,
+		Lookback:        cfg.Documents.Lookback,
+		BatchSize:       cfg.Documents.BatchSize,
+		MaxUploadBytes:  cfg.Storage.MaxUploadBytes,
+		Retention:       retention,
+		Location:        location,
+		Currency:        strings.ToUpper(cfg.Payments.Currency),
+		MinorUnitDigits: payments.MinorUnitDigits,
+	}, nil
+}
+
+// initializeServices records every customer event in the event log before it
+// goes to RabbitMQ, so that the replay service can publish it again; only
+// contact verification codes skip the log. The returned buffer batches the
+// events of bulk producers on the same chain.
+func initializeServices(rabbitConn *amqp.Connection, exchangeName string, repos *database.Repositories, hub *event.Hub, events config.EventsConfig, payments loan.PaymentPolicy, delinquency loan.DelinquencyPolicySource, taxes loan.TaxPolicy, credit loan.CreditPolicy, products loan.ProductCatalog, duplicates customer.DuplicatePolicy, clk clock.Clock, logger *slog.Logger) (loan.LoanService, customer.CustomerService, contact.Service, event.ReplayService, *event.Buffer) {
 	logger.Info("Initializing application components...")
-	loanRepo := postgres.NewLoanRepository(dbPool, logger)
-	customerRepo := postgres.NewCustomerRepository(dbPool, logger)
-	eventPublisher, _ := event.NewRabbitMQEventPublisher(rabbitConn, "billing-engine", logger)
-	customerService := customer.NewCustomerService(customerRepo, eventPublisher, logger)
-	return loan.NewLoanService(loanRepo, customerService, logger), customerService, loanRepo
+	rabbitPublisher, _ := event.NewRabbitMQEventPublisher(rabbitConn, exchangeName, logger)
+	eventPublisher := event.NewStreamingPublisher(event.NewRecordingPublisher(rabbitPublisher, repos.Events, clk, logger), hub)
+	customerService := customer.NewCustomerService(repos.Customers, eventPublisher, duplicates, clk, logger)
+	contactService := contact.NewService(repos.Contacts, eventPublisher, clk, logger)
+	loanService := loan.NewStreamingLoanService(loan.NewLoanService(repos.Loans, customerService, payments, delinquency, taxes, credit, products, clk, logger), hub, clk)
+	replayService := event.NewReplayService(repos.Events, rawPublisher(rabbitPublisher), clk, logger)
+	eventBuffer := event.NewBuffer(eventPublisher, events.PublishBatchSize, events.PublishFlushInterval, logger)
+	return loanService, customerService, contactService, replayService, eventBuffer
+}
+
+// rawPublisher is nil when RabbitMQ is not connected.
+func rawPublisher(p event.EventPublisher) event.RawPublisher {
+	raw, _ := p.(event.RawPublisher)
+	return raw
+}
+
+// setupObjectStore returns nil when no storage endpoint is configured, which
+// leaves notes available and turns attachment uploads and payoff
+// certificates off.
+func setupObjectStore(cfg *config.Config, logger *slog.Logger) *storage.S3Store {
+	if cfg.Storage.Endpoint == "" {
+		logger.Warn("Object storage is not configured, attachments and payoff certificates are disabled")
+		return nil
+	}
+	store, err := storage.NewS3Store(cfg.Storage, nil, logger)
+	if err != nil {
+		logger.Error("Failed to configure object storage, attachments and payoff certificates are disabled", "error", err)
+		return nil
+	}
+	logger.Info("Object storage configured", "endpoint", cfg.Storage.Endpoint, "bucket", cfg.Storage.Bucket)
+	return store
+}
+
+// setupNotificationSource returns the notify-service client the customer
+// overview reads notifications through, or nil to leave them out.
+func setupNotificationSource(cfg *config.Config, logger *slog.Logger) overview.NotificationSource {
+	if cfg.Notify.URL == "" {
+		logger.Warn("No notify-service URL is configured, customer overviews carry no notifications")
+		return nil
+	}
+	client, err := notify.NewClient(cfg.Notify, nil, logger)
+	if err != nil {
+		logger.Error("Failed to configure the notify-service client, customer overviews carry no notifications", "error", err)
+		return nil
+	}
+	logger.Info("Customer overviews read notifications from notify-service", "url", cfg.Notify.URL)
+	return client
+}
+
+// setupESignProvider returns the e-signature provider that workflow loans
+// send their agreement through, or nil, which turns workflow mode off.
+func setupESignProvider(cfg *config.Config, logger *slog.Logger) agreement.Provider {
+	provider, err := esign.New(cfg.ESign, nil)
+	switch {
+	case err != nil:
+		logger.Error("Failed to configure the e-signature provider, loans cannot be created in workflow mode", "error", err)
+		return nil
+	case provider == nil:
+		logger.Info("No e-signature provider is configured, loans cannot be created in workflow mode")
+		return nil
+	}
+	logger.Info("Workflow loan agreements are sent for e-signature", "provider", provider.Name(), "url", cfg.ESign.URL)
+	return provider
+}
+
+// setupAccessList keeps the rate limit blocklist and allowlist in Redis so
+// that every instance enforces them, or in memory when Redis is not
+// configured.
+func setupAccessList(cfg *config.Config, logger *slog.Logger) *ratelimit.AccessList {
+	var store ratelimit.Store
+	client, err := redis.NewClient(cfg.Redis)
+	switch {
+	case cfg.Redis.Addr == "":
+		logger.Warn("Redis is not configured, rate limit access lists are kept per instance")
+		store = ratelimit.NewMemoryStore()
+	case err != nil:
+		logger.Error("Failed to configure Redis, rate limit access lists are kept per instance", "error", err)
+		store = ratelimit.NewMemoryStore()
+	default:
+		logger.Info("Rate limit access lists kept in Redis", "addr", cfg.Redis.Addr)
+		store = redis.NewAccessListStore(client, cfg.Redis.KeyPrefix)
+	}
+	return ratelimit.NewAccessList(store, cfg.Server.RateLimit.ListRefreshInterval, logger)
+}
+
+// setupOutstandingCache caches outstanding amounts in Redis, where a write on
+// any instance drops the amount for all of them, or in memory when Redis is
+// not configured. A TTL of zero leaves the loan service uncached.
+func setupOutstandingCache(cfg *config.Config, loanService loan.LoanService, clk clock.Clock, logger *slog.Logger) loan.LoanService {
+	ttl := cfg.Cache.OutstandingTTL
+	if ttl <= 0 {
+		logger.Info("Outstanding amount cache is disabled")
+		return loanService
+	}
+	var cache loan.OutstandingCache
+	client, err := redis.NewClient(cfg.Redis)
+	switch {
+	case cfg.Redis.Addr == "":
+		logger.Warn("Redis is not configured, outstanding amounts are cached per instance", "ttl", ttl)
+		cache = loan.NewMemoryOutstandingCache(ttl, nil)
+	case err != nil:
+		logger.Error("Failed to configure Redis, outstanding amounts are cached per instance", "ttl", ttl, "error", err)
+		cache = loan.NewMemoryOutstandingCache(ttl, nil)
+	default:
+		logger.Info("Outstanding amounts cached in Redis", "addr", cfg.Redis.Addr, "ttl", ttl)
+		cache = redis.NewOutstandingCache(client, cfg.Redis.KeyPrefix, ttl)
+	}
+	return loan.NewCachingLoanService(loanService, cache, clk, logger)
 }
 
 func startServer(cfg *config.Config, router http.Handler, logger *slog.Logger) (*http.Server, <-chan error, <-chan os.Signal) {
@@ -107,6 +518,15 @@ func startServer(cfg *config.Config, router http.Handler, logger *slog.Logger) (
 		IdleTimeout:  cfg.Server.IdleTimeout,
 		ErrorLog:     slog.NewLogLogger(logger.Handler(), slog.LevelError),
 	}
+	if cfg.Server.TLS.Enabled() {
+		reloader, err := tlsconfig.NewReloader(cfg.Server.TLS, logger)
+		if err != nil {
+			logger.Error("Failed to configure TLS", "error", err)
+			os.Exit(1)
+		}
+		srv.TLSConfig = reloader.TLSConfig()
+		logger.Info("TLS enabled", "cert_file", cfg.Server.TLS.CertFile, "client_ca_file", cfg.Server.TLS.ClientCAFile)
+	}
 
 	shutdownChan := make(chan os.Signal, 1)
 	signal.Notify(shutdownChan, syscall.SIGINT, syscall.SIGTERM)
@@ -114,7 +534,13 @@ func startServer(cfg *config.Config, router http.Handler, logger *slog.Logger) (
 	serverErrors := make(chan error, 1)
 	go func() {
 		logger.Info(fmt.Sprintf("Server listening on port %d", cfg.Server.Port))
-		err := srv.ListenAndServe()
+		var err error
+		if srv.TLSConfig != nil {
+			// The certificate comes from TLSConfig so it can be reloaded.
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			err = srv.ListenAndServe()
+		}
 		if !errors.Is(err, http.ErrServerClosed) {
 			logger.Error("Server error", "error", err)
 			serverErrors <- err
@@ -126,7 +552,7 @@ func startServer(cfg *config.Config, router http.Handler, logger *slog.Logger) (
 	return srv, serverErrors, shutdownChan
 }
 
-func handleShutdown(srv *http.Server, cronScheduler *cron.Cron, rabbitConn *amqp.Connection,
+func handleShutdown(srv *http.Server, cronScheduler *cron.Cron, jobRunner *jobs.Runner, eventBuffer *event.Buffer, rabbitConn *amqp.Connection,
 	shutdownChan <-chan os.Signal, serverErrors <-chan error, logger *slog.Logger) {
 	logger.Info("Shutdown handler started. Waiting for signal or server error...")
 
@@ -135,6 +561,8 @@ func handleShutdown(srv *http.Server, cronScheduler *cron.Cron, rabbitConn *amqp
 	logger.Info("Starting graceful shutdown...", "trigger", triggerReason)
 
 	stopCronScheduler(cronScheduler, logger)
+	stopJobRunner(jobRunner, logger)
+	stopEventBuffer(eventBuffer, logger)
 	closeRabbitMQConnection(rabbitConn, logger)
 	shutdownHTTPServer(srv, serverErrors, logger)
 
@@ -167,6 +595,28 @@ func stopCronScheduler(cronScheduler *cron.Cron, logger *slog.Logger) {
 	}
 }
 
+// stopJobRunner lets the bulk jobs already accepted finish before the events
+// they publish are flushed.
+func stopJobRunner(jobRunner *jobs.Runner, logger *slog.Logger) {
+	logger.Info("Waiting for bulk jobs to finish...")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := jobRunner.Stop(ctx); err != nil {
+		logger.Warn("Bulk jobs still running at shutdown were cancelled", slog.Any("error", err))
+	}
+}
+
+// stopEventBuffer publishes the events still queued while RabbitMQ is
+// connected.
+func stopEventBuffer(eventBuffer *event.Buffer, logger *slog.Logger) {
+	logger.Info("Flushing buffered events...")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := eventBuffer.Stop(ctx); err != nil {
+		logger.Error("Failed to publish buffered events, they stay in the event log for replay", slog.Any("error", err))
+	}
+}
+
 func closeRabbitMQConnection(rabbitConn *amqp.Connection, logger *slog.Logger) {
 	if rabbitConn != nil && !rabbitConn.IsClosed() {
 		logger.Info("Closing RabbitMQ connection...")
@@ -213,110 +663,283 @@ func shutdownHTTPServer(srv *http.Server, serverErrors <-chan error, logger *slo
 	}
 }
 
-func startBatchJobs(cfg *config.Config, logger *slog.Logger, updateJob *batch.UpdateDelinquencyJob) *cron.Cron {
+// batchJobNames are the jobs startBatchJobs may schedule, which
+// batch.timezones, batch.overlaps and batch.watchdog.maxRuntimes may name.
+var batchJobNames = []string{"DelinquencyUpdate", "LoanSnapshot", "CollectionsAssignment", "ReminderEscalation", "SummaryRebuild",
+	"IntegrityCheck", "PartitionMaintenance", "DirectDebit", "LoanArchive", "PayoffCertificates", "DocumentRetention"}
+
+// startBatchJobs schedules the daily jobs, the weekly direct-debit run when
+// directDebitJob is not nil, the loan archive run when archiveJob is not and
+// the payoff certificate and document retention runs when certificateJob
+// and documentRetentionJob are not.
+// The runs are queued on runner, which must not have been started yet, and
+// recorded by recorder. Schedules are read in batch.timezone unless
+// batch.timezones names another zone for the job. Every job it is given
+// must be scheduled: an invalid spec or zone fails startup with all of the
+// errors rather than leaving a job that never runs. Each job is watched by
+// watchdog unless it is nil, and its runs overlap as batch.overlap, or
+// batch.overlaps for the job, says through guard.
+func startBatchJobs(cfg *config.Config, runner *jobs.Runner, recorder *jobs.RunRecorder, watchdog *jobs.Watchdog, guard *jobs.OverlapGuard, logger *slog.Logger, updateJob *batch.UpdateDelinquencyJob, snapshotJob *batch.LoanSnapshotJob, collectionsJob *batch.CollectionsAssignmentJob, reminderJob *batch.ReminderEscalationJob, summaryJob *batch.SummaryRebuildJob, integrityJob *batch.IntegrityCheckJob, partitionJob *batch.PartitionMaintenanceJob, directDebitJob *batch.DirectDebitJob, archiveJob *batch.LoanArchiveJob, certificateJob *batch.PayoffCertificateJob, documentRetentionJob *batch.DocumentRetentionJob) (*cron.Cron, error) {
 	logger.Info("Initializing batch job scheduler...")
-	c := cron.New()
-
-	scheduleSpec := cfg.Batch.DelinquencyUpdateSchedule
-	if scheduleSpec == "" {
-		scheduleSpec = "0 2 * * *"
-		logger.Warn("Batch delinquency update schedule not configured, using default", "schedule", scheduleSpec)
+	location, err := time.LoadLocation(cfg.Batch.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("batch.timezone %q: %w", cfg.Batch.Timezone, err)
 	}
-	jobTimeout := cfg.Batch.DelinquencyUpdateTimeout
-	if jobTimeout <= 0 {
-		jobTimeout = 1 * time.Hour
-	} else {
-		jobTimeout = jobTimeout * time.Second
+	defaultOverlap, err := jobs.ParseOverlapPolicy(cfg.Batch.Overlap)
+	if err != nil {
+		return nil, fmt.Errorf("batch.overlap: %w", err)
+	}
+	var errs []error
+	checkNames := func(key string, names iter.Seq[string]) {
+		for name := range names {
+			// viper lowercases map keys, so names are matched ignoring case.
+			if !slices.ContainsFunc(batchJobNames, func(known string) bool { return strings.EqualFold(known, name) }) {
+				errs = append(errs, fmt.Errorf("%s names %q, which is not a batch job", key, name))
+			}
+		}
+	}
+	checkNames("batch.timezones", maps.Keys(cfg.Batch.Timezones))
+	checkNames("batch.overlaps", maps.Keys(cfg.Batch.Overlaps))
+	checkNames("batch.watchdog.maxRuntimes", maps.Keys(cfg.Batch.Watchdog.MaxRuntimes))
+	c := cron.New(cron.WithLocation(location))
+	schedule := func(name, scheduleSpec, defaultSpec string, timeoutSeconds time.Duration, run func(context.Context) error) {
+		timezone, _ := jobSetting(cfg.Batch.Timezones, name)
+		overlap := defaultOverlap
+		if setting, ok := jobSetting(cfg.Batch.Overlaps, name); ok {
+			var err error
+			if overlap, err = jobs.ParseOverlapPolicy(setting); err != nil {
+				errs = append(errs, fmt.Errorf("batch job %s: %w", name, err))
+				return
+			}
+		}
+		jobSchedule, err := scheduleJob(c, runner, recorder, guard, logger, name, scheduleSpec, defaultSpec, timezone, overlap, timeoutSeconds, run)
+		if err != nil {
+			errs = append(errs, err)
+			return
+		}
+		if watchdog != nil {
+			maxRuntime, ok := jobSetting(cfg.Batch.Watchdog.MaxRuntimes, name)
+			if !ok {
+				maxRuntime = jobTimeout(timeoutSeconds)
+			}
+			watchdog.Watch(name, jobSchedule, maxRuntime)
+		}
 	}
 
-	jobID, err := c.AddJob(scheduleSpec, cron.FuncJob(func() {
-		jobLogger := logger.With("job_name", "DelinquencyUpdate")
-		jobLogger.Info("Cron triggered: Running delinquency update job.")
+	schedule("DelinquencyUpdate", cfg.Batch.DelinquencyUpdateSchedule, "0 2 * * *", cfg.Batch.DelinquencyUpdateTimeout, updateJob.Run)
+	schedule("LoanSnapshot", cfg.Batch.SnapshotSchedule, "50 23 * * *", cfg.Batch.SnapshotTimeout, snapshotJob.Run)
+	schedule("CollectionsAssignment", cfg.Collections.Schedule, "30 2 * * *", cfg.Collections.Timeout, collectionsJob.Run)
+	schedule("ReminderEscalation", cfg.Collections.EscalationSchedule, "45 2 * * *", cfg.Collections.EscalationTimeout, reminderJob.Run)
+	schedule("SummaryRebuild", cfg.Batch.SummarySchedule, "0 3 * * *", cfg.Batch.SummaryTimeout, summaryJob.Run)
+	schedule("IntegrityCheck", cfg.Batch.IntegritySchedule, "30 3 * * *", cfg.Batch.IntegrityTimeout, integrityJob.Run)
+	schedule("PartitionMaintenance", cfg.Batch.PartitionSchedule, "15 1 * * *", cfg.Batch.PartitionTimeout, partitionJob.Run)
+	if directDebitJob != nil {
+		schedule("DirectDebit", cfg.DirectDebit.Schedule, "0 6 * * 1", cfg.DirectDebit.Timeout, directDebitJob.Run)
+	}
+	if archiveJob != nil {
+		schedule("LoanArchive", cfg.Retention.Schedule, "0 4 * * 0", cfg.Retention.Timeout, archiveJob.Run)
+	}
+	if certificateJob != nil {
+		schedule("PayoffCertificates", cfg.Documents.Schedule, "*/15 * * * *", cfg.Documents.Timeout, certificateJob.Run)
+	}
+	if documentRetentionJob != nil {
+		schedule("DocumentRetention", cfg.Documents.RetentionSchedule, "0 5 * * *", cfg.Documents.RetentionTimeout, documentRetentionJob.Run)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
 
-		ctx, cancel := context.WithTimeout(context.Background(), jobTimeout)
-		defer cancel()
+	c.Start()
+	logger.Info("Cron scheduler started.", "timezone", location.String())
+	return c, nil
+}
 
-		if runErr := updateJob.Run(ctx); runErr != nil {
-			jobLogger.Error("Delinquency update job finished with error", slog.Any("error", runErr))
-		} else {
-			jobLogger.Info("Delinquency update job finished successfully.")
+// jobSetting returns what settings, keyed by job name, sets for the job
+// called name and whether it sets anything.
+func jobSetting[V any](settings map[string]V, name string) (V, bool) {
+	for key, value := range settings {
+		if strings.EqualFold(key, name) {
+			return value, true
 		}
-	}))
+	}
+	var zero V
+	return zero, false
+}
 
-	if err != nil {
-		logger.Error("Failed to schedule delinquency update job", "schedule", scheduleSpec, slog.Any("error", err))
+// jobTimeout converts a batch job timeout in seconds; zero or less means one
+// hour.
+func jobTimeout(timeoutSeconds time.Duration) time.Duration {
+	if timeoutSeconds <= 0 {
+		return time.Hour
+	}
+	return timeoutSeconds * time.Second
+}
 
+// scheduleJob registers run as the job kind batch.<name> on runner and has c
+// queue a run of it on schedule. Every instance schedules the same runs; the
+// dedupe key, made of the name and the scheduled time, lets only the first
+// queue each of them, and whichever instance claims it runs it.
+// timeoutSeconds bounds a single run; zero or less means one hour. Each run
+// is recorded by recorder under name. A timezone other than "" reads the
+// schedule in that zone instead of the location of c. guard applies the
+// overlap policy to the runs: a run that would overlap the previous one and
+// is skipped, or waits longer than the timeout, ends without running. It
+// returns the parsed schedule; nothing is registered when the schedule or
+// the zone is invalid.
+func scheduleJob(c *cron.Cron, runner *jobs.Runner, recorder *jobs.RunRecorder, guard *jobs.OverlapGuard, logger *slog.Logger, name, scheduleSpec, defaultSpec, timezone string, overlap jobs.OverlapPolicy, timeoutSeconds time.Duration, run func(context.Context) error) (cron.Schedule, error) {
+	if scheduleSpec == "" {
+		scheduleSpec = defaultSpec
+		logger.Warn("Batch job schedule not configured, using default", "job_name", name, "schedule", scheduleSpec)
+	}
+	if timezone != "" {
+		if _, err := time.LoadLocation(timezone); err != nil {
+			return nil, fmt.Errorf("batch job %s: timezone %q: %w", name, timezone, err)
+		}
 	} else {
-		logger.Info("Scheduled delinquency update job", "schedule", scheduleSpec, "job_id", jobID)
+		// Named even then, so that the schedule reads the same with any
+		// time, as the watchdog passes it, and not only with the times of c.
+		timezone = c.Location().String()
 	}
+	spec := "CRON_TZ=" + timezone + " " + scheduleSpec
+	jobSchedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return nil, fmt.Errorf("batch job %s: schedule %q: %w", name, scheduleSpec, err)
+	}
+	timeout := jobTimeout(timeoutSeconds)
+
+	kind := "batch." + name
+	recorder.Register(name)
+	runner.Register(kind, func(ctx context.Context, job jobs.Job, _ func(int)) (any, error) {
+		jobLogger := logger.With("job_name", name)
+		jobLogger.Info("Running batch job.")
+
+		runErr := guard.Run(ctx, name, job.ID, overlap, timeout, func(ctx context.Context) error {
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			start := time.Now()
+			runErr := recorder.Track(ctx, name, job.ID, run)
+			monitoring.RecordBatchJob(name, runErr, time.Since(start), time.Now())
+			return runErr
+		})
+		switch {
+		case errors.Is(runErr, jobs.ErrRunOverlapped):
+			monitoring.RecordBatchJobSkipped(name)
+			jobLogger.Warn("Batch job run skipped: the previous run is still going", "overlap", overlap)
+			return nil, nil
+		case runErr != nil:
+			jobLogger.Error("Batch job finished with error", slog.Any("error", runErr))
+		default:
+			jobLogger.Info("Batch job finished successfully.")
+		}
+		return nil, runErr
+	})
+
+	var jobID cron.EntryID
+	jobID = c.Schedule(jobSchedule, cron.FuncJob(func() {
+		jobLogger := logger.With("job_name", name)
+		scheduled := c.Entry(jobID).Prev
+		if scheduled.IsZero() {
+			scheduled = time.Now().Truncate(time.Minute)
+		}
+		job, err := runner.Submit(context.Background(), jobs.Spec{
+			Kind: kind, DedupeKey: kind + "@" + scheduled.UTC().Format(time.RFC3339), Scheduled: true,
+		})
+		switch {
+		case errors.Is(err, apperrors.ErrAlreadyExists):
+			jobLogger.Debug("Cron triggered: batch job already queued by another instance.")
+		case err != nil:
+			jobLogger.Error("Failed to queue batch job", slog.Any("error", err))
+		default:
+			jobLogger.Info("Cron triggered: batch job queued.", "job_id", job.ID)
+		}
+	}))
 
-	c.Start()
-	logger.Info("Cron scheduler started.")
-	return c
+	logger.Info("Scheduled batch job", "job_name", name, "schedule", spec, "job_id", jobID)
+	return jobSchedule, nil
 }
 
-func setupLogger(cfg config.LoggerConfig) *slog.Logger {
-	return logging.NewLogger(cfg)
+func setupLogger(cfg config.LoggerConfig, reporter errorreport.Reporter) *slog.Logger {
+	return logging.NewLogger(cfg, reporter)
 }
 
+// rabbitMQDialRetry spends about half a minute on a broker that is still
+// starting, as it may be when everything comes up together.
+var rabbitMQDialRetry = retry.Policy{MaxAttempts: 5, Initial: 2 * time.Second, Max: 15 * time.Second, Jitter: 0.2}
+
 func connectRabbitMQ(uri string, logger *slog.Logger) (*amqp.Connection, error) {
 	var conn *amqp.Connection
-	var err error
-	retryCount := 5
-	for i := 1; i <= retryCount; i++ {
-		conn, err = amqp.Dial(uri)
-		if err == nil {
-			logger.Info("Successfully connected to RabbitMQ")
-
-			go func() {
-				blockChan := conn.NotifyBlocked(make(chan amqp.Blocking))
-				closeChan := conn.NotifyClose(make(chan *amqp.Error))
-
-				select {
-				case b := <-blockChan:
-					logger.Warn("RabbitMQ Connection Blocked", "reason", b.Reason)
-				case e := <-closeChan:
-					logger.Error("RabbitMQ Connection Closed", slog.Any("error", e))
-				}
-			}()
-
-			return conn, nil
-		}
+	policy := rabbitMQDialRetry
+	policy.OnRetry = func(attempt int, err error, wait time.Duration) {
 		logger.Warn("Failed to connect to RabbitMQ, retrying...",
-			slog.Int("attempt", i),
-			slog.Int("max_attempts", retryCount),
+			slog.Int("attempt", attempt),
+			slog.Int("max_attempts", policy.MaxAttempts),
+			slog.Duration("wait", wait),
 			slog.Any("error", err),
 		)
-		time.Sleep(time.Duration(i*2) * time.Second)
 	}
-	return nil, fmt.Errorf("failed to connect to RabbitMQ after %d attempts: %w", retryCount, err)
+	err := retry.Do(context.Background(), policy, func(context.Context, int) error {
+		var err error
+		conn, err = amqp.Dial(uri)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to RabbitMQ after %d attempts: %w", policy.MaxAttempts, err)
+	}
+	logger.Info("Successfully connected to RabbitMQ")
+
+	go func() {
+		blockChan := conn.NotifyBlocked(make(chan amqp.Blocking))
+		closeChan := conn.NotifyClose(make(chan *amqp.Error))
+
+		select {
+		case b := <-blockChan:
+			logger.Warn("RabbitMQ Connection Blocked", "reason", b.Reason)
+		case e := <-closeChan:
+			logger.Error("RabbitMQ Connection Closed", slog.Any("error", e))
+		}
+	}()
+
+	return conn, nil
 }
 
-func setupRabbitMQ(cfg *config.Config, logger *slog.Logger) (*amqp.Connection, error) {
-	rabbitMQURI := cfg.RabbitMQ.Host
+// rabbitMQURI builds the broker URI from the host, port and credentials.
+func rabbitMQURI(cfg config.RabbitMQConfig) (string, error) {
+	uri := cfg.Host
 
-	if rabbitMQURI == "" {
-		return nil, fmt.Errorf("RabbitMQ host is not configured")
+	if uri == "" {
+		return "", fmt.Errorf("RabbitMQ host is not configured")
 	}
 
-	if cfg.RabbitMQ.Port != 0 {
-		rabbitMQURI = fmt.Sprintf("amqp://%s:%d", cfg.RabbitMQ.Host, cfg.RabbitMQ.Port)
+	if cfg.Port != 0 {
+		uri = fmt.Sprintf("amqp://%s:%d", cfg.Host, cfg.Port)
 	}
 
-	if cfg.RabbitMQ.Username != "" && cfg.RabbitMQ.Password != "" {
-		rabbitMQURI = fmt.Sprintf("amqp://%s:%s@%s:%d", cfg.RabbitMQ.Username, cfg.RabbitMQ.Password, cfg.RabbitMQ.Host, cfg.RabbitMQ.Port)
-	} else if cfg.RabbitMQ.Username != "" || cfg.RabbitMQ.Password != "" {
-		return nil, fmt.Errorf("RabbitMQ username and password must be provided together")
+	if cfg.Username != "" && cfg.Password != "" {
+		uri = fmt.Sprintf("amqp://%s:%s@%s:%d", cfg.Username, cfg.Password, cfg.Host, cfg.Port)
+	} else if cfg.Username != "" || cfg.Password != "" {
+		return "", fmt.Errorf("RabbitMQ username and password must be provided together")
 	}
+	return uri, nil
+}
 
-	if rabbitMQURI == "" {
-		return nil, fmt.Errorf("RabbitMQ URI is not configured")
+func setupRabbitMQ(cfg *config.Config, logger *slog.Logger) (*amqp.Connection, error) {
+	uri, err := rabbitMQURI(cfg.RabbitMQ)
+	if err != nil {
+		return nil, err
 	}
 
-	conn, err := connectRabbitMQ(rabbitMQURI, logger)
+	conn, err := connectRabbitMQ(uri, logger)
 	if err != nil {
 		logger.Error("Failed to connect to RabbitMQ", "error", err)
 		return nil, err
 	}
+	// Without the topology publishes could go unrouted, so the service runs
+	// as if RabbitMQ were down and keeps the events in the log for replay.
+	if err := topology.Declare(conn, cfg.RabbitMQ.Topology, logger); err != nil {
+		logger.Error("Failed to declare RabbitMQ topology", "error", err)
+		_ = conn.Close()
+		return nil, err
+	}
 	return conn, nil
 }
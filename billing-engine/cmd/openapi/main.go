@@ -0,0 +1,35 @@
+// Command openapi writes the OpenAPI 3 document and regenerates the typed
+// client in pkg/client from it.
+package main
+
+import (
+	"billing-engine/internal/api/openapi"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+)
+
+func main() {
+	specPath := flag.String("spec", "docs/openapi.json", "output path of the OpenAPI document")
+	clientPath := flag.String("client", "pkg/client/client_gen.go", "output path of the generated client")
+	flag.Parse()
+
+	doc := openapi.Build()
+
+	spec, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		log.Fatalf("marshal OpenAPI document: %v", err)
+	}
+	if err := os.WriteFile(*specPath, append(spec, '\n'), 0o644); err != nil {
+		log.Fatalf("write %s: %v", *specPath, err)
+	}
+
+	src, err := openapi.GenerateClient(doc, "client")
+	if err != nil {
+		log.Fatalf("generate client: %v", err)
+	}
+	if err := os.WriteFile(*clientPath, src, 0o644); err != nil {
+		log.Fatalf("write %s: %v", *clientPath, err)
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"billing-engine/internal/event"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+)
+
+const replayUsage = `Usage: billing-engine replay [flags]
+
+Publishes events from the event log to RabbitMQ again, under their original
+IDs, so that a consumer that was down can be backfilled. At least one of
+-entity, -types, -since, -until or -unpublished is required.
+
+`
+
+type replayOptions struct {
+	filter event.ReplayFilter
+	dryRun bool
+}
+
+func parseReplayFlags(args []string, output io.Writer) (replayOptions, error) {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.Usage = func() {
+		fmt.Fprint(output, replayUsage)
+		fs.PrintDefaults()
+	}
+	entity := fs.Int64("entity", 0, "customer the events are about")
+	types := fs.String("types", "", "comma separated event types, e.g. customer.created,customer.updated")
+	since := fs.String("since", "", "earliest occurrence, RFC 3339, inclusive")
+	until := fs.String("until", "", "latest occurrence, RFC 3339, exclusive")
+	unpublished := fs.Bool("unpublished", false, "only events the broker never accepted")
+	limit := fs.Int("limit", 0, fmt.Sprintf("maximum number of events, at most %d (default 1000)", event.MaxReplayEvents))
+	dryRun := fs.Bool("dry-run", false, "list the matching events without publishing them")
+	if err := fs.Parse(args); err != nil {
+		return replayOptions{}, err
+	}
+
+	opts := replayOptions{
+		filter: event.ReplayFilter{UnpublishedOnly: *unpublished, Limit: *limit},
+		dryRun: *dryRun,
+	}
+	if *entity != 0 {
+		opts.filter.EntityID = entity
+	}
+	if *types != "" {
+		opts.filter.Types = strings.Split(*types, ",")
+	}
+	for name, raw := range map[string]string{"since": *since, "until": *until} {
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return replayOptions{}, fmt.Errorf("-%s must be an RFC 3339 timestamp: %w", name, err)
+		}
+		if name == "since" {
+			opts.filter.Since = &t
+		} else {
+			opts.filter.Until = &t
+		}
+	}
+	return opts, nil
+}
+
+// runReplay implements the replay subcommand and returns the exit code. It
+// uses the service's configuration, so it runs where the service runs.
+func runReplay(args []string) int {
+	opts, err := parseReplayFlags(args, os.Stderr)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	cfg, logger, _ := initializeApp()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	repos := initializeDatabase(cfg, clock.System(), logger)
+	defer closeDatabase(repos, logger)
+
+	if opts.dryRun {
+		records, err := event.NewReplayService(repos.Events, nil, clock.System(), logger).Find(ctx, opts.filter)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		printRecords(os.Stdout, records)
+		return 0
+	}
+
+	conn, err := setupRabbitMQ(cfg, logger)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer closeRabbitMQConnection(conn, logger)
+	publisher, err := event.NewRabbitMQEventPublisher(conn, cfg.RabbitMQ.ExchangeName, logger)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	report, err := event.NewReplayService(repos.Events, rawPublisher(publisher), clock.System(), logger).Replay(ctx, opts.filter)
+	if report != nil {
+		fmt.Fprintf(os.Stdout, "matched %d, replayed %d, failed %d\n", report.Matched, report.Replayed, len(report.Failed))
+		for _, id := range report.Failed {
+			fmt.Fprintln(os.Stdout, "failed:", id)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if len(report.Failed) > 0 {
+		return 1
+	}
+	return 0
+}
+
+func printRecords(w io.Writer, records []event.Record) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tEVENT ID\tTYPE\tENTITY\tOCCURRED AT\tPUBLISHED\tREPLAYS")
+	for _, r := range records {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%s\t%t\t%d\n", r.ID, r.EventID, r.Type, r.EntityID,
+			r.OccurredAt.UTC().Format(time.RFC3339), r.PublishedAt != nil, r.ReplayCount)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "%d events\n", len(records))
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"billing-engine/internal/event"
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReplayFlags(t *testing.T) {
+	opts, err := parseReplayFlags([]string{"-entity", "7", "-types", "customer.created,customer.updated",
+		"-since", "2025-03-01T00:00:00Z", "-unpublished", "-limit", "50", "-dry-run"}, io.Discard)
+
+	require.NoError(t, err)
+	assert.True(t, opts.dryRun)
+	require.NotNil(t, opts.filter.EntityID)
+	assert.Equal(t, int64(7), *opts.filter.EntityID)
+	assert.Equal(t, []string{"customer.created", "customer.updated"}, opts.filter.Types)
+	assert.Equal(t, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), *opts.filter.Since)
+	assert.Nil(t, opts.filter.Until)
+	assert.True(t, opts.filter.UnpublishedOnly)
+	assert.Equal(t, 50, opts.filter.Limit)
+
+	_, err = parseReplayFlags([]string{"-until", "yesterday"}, io.Discard)
+	assert.Error(t, err)
+}
+
+func TestPrintRecords(t *testing.T) {
+	var out bytes.Buffer
+	published := time.Date(2025, 3, 1, 8, 5, 0, 0, time.UTC)
+	printRecords(&out, []event.Record{{ID: 1, EventID: "e-1", Type: event.TypeCustomerCreated, EntityID: 7,
+		OccurredAt: time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC), PublishedAt: &published}})
+
+	assert.Contains(t, out.String(), "e-1")
+	assert.Contains(t, out.String(), "2025-03-01T08:00:00Z")
+	assert.Contains(t, out.String(), "1 events")
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/pkg/clock"
+	"billing-engine/internal/seed"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const seedUsage = `Usage: billing-engine seed [flags]
+
+Creates -customers made-up customers for local development, demos and load
+tests against a scratch database. The customers get a loan at a stage picked
+by -mix, from the stages none (no loan), new, current, delinquent and
+paid_off, with the payments and the delinquency flags that stage implies.
+The same -seed and -as-of create the same data. The IDs of the new loans are
+printed one per line and a count per stage on standard error.
+
+The rows are written straight to the database and no events are published;
+the customer summaries are rebuilt at the end. Never run it against
+production.
+
+`
+
+const defaultSeedMix = "none=10,new=15,current=45,delinquent=20,paid_off=10"
+
+type seedOptions struct {
+	seed.Options
+	output string
+}
+
+func parseSeedFlags(args []string, output io.Writer, now time.Time) (seedOptions, error) {
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.Usage = func() {
+		fmt.Fprint(output, seedUsage)
+		fs.PrintDefaults()
+	}
+	customers := fs.Int("customers", 1000, "number of customers to create")
+	mix := fs.String("mix", defaultSeedMix, "stage=weight pairs weighing how often each stage is created")
+	seedValue := fs.Uint64("seed", 1, "seed of the random data")
+	asOf := fs.String("as-of", "", "day the data is created as of, YYYY-MM-DD (default today)")
+	out := fs.String("out", "", "file to write the loan IDs to instead of standard output")
+	if err := fs.Parse(args); err != nil {
+		return seedOptions{}, err
+	}
+	if *customers <= 0 {
+		return seedOptions{}, errors.New("-customers must be positive")
+	}
+	parsedMix, err := seed.ParseMix(*mix)
+	if err != nil {
+		return seedOptions{}, fmt.Errorf("-mix: %w", err)
+	}
+
+	opts := seedOptions{Options: seed.Options{Customers: *customers, Mix: parsedMix, Seed: *seedValue, Now: now}, output: *out}
+	if *asOf != "" {
+		day, err := time.Parse(time.DateOnly, *asOf)
+		if err != nil {
+			return seedOptions{}, fmt.Errorf("-as-of must be a date such as 2025-03-03: %w", err)
+		}
+		// Midday, so that the payments of the day are before it.
+		opts.Now = day.Add(12 * time.Hour)
+	}
+	return opts, nil
+}
+
+// runSeed implements the seed subcommand and returns the exit code. It writes
+// to the database the service's configuration names.
+func runSeed(args []string) int {
+	opts, err := parseSeedFlags(args, os.Stderr, time.Now().UTC())
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	out := io.Writer(os.Stdout)
+	if opts.output != "" {
+		f, err := os.Create(opts.output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer f.Close()
+		out = f
+	}
+
+	cfg, logger, _ := initializeApp()
+	delinquency, err := loan.NewDelinquencyPolicy(cfg.Delinquency.MissedPayments, cfg.Delinquency.CurePayments, cfg.Delinquency.GraceDays, cfg.Delinquency.LateFee)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "invalid delinquency configuration:", err)
+		return 1
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	repos := initializeDatabase(cfg, clock.System(), logger)
+	defer closeDatabase(repos, logger)
+
+	generator := seed.NewGenerator(repos.Customers, repos.Loans, delinquency, logger)
+	report, err := generator.Run(ctx, opts.Options, func(loanID int64, _ seed.Stage) error {
+		_, err := fmt.Fprintln(out, loanID)
+		return err
+	})
+	if report != nil {
+		fmt.Fprintf(os.Stderr, "created %d customers and %d payments\n", report.Customers, report.Payments)
+		for _, stage := range seed.Stages[1:] {
+			fmt.Fprintf(os.Stderr, "  %-10s %d loans\n", stage, report.Loans[stage])
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if _, err := repos.Summaries.Rebuild(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to rebuild the customer summaries:", err)
+		return 1
+	}
+	return 0
+}
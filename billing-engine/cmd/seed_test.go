@@ -0,0 +1,36 @@
+package main
+
+import (
+	"billing-engine/internal/seed"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSeedFlags(t *testing.T) {
+	now := time.Date(2025, 3, 10, 15, 4, 0, 0, time.UTC)
+
+	opts, err := parseSeedFlags(nil, io.Discard, now)
+	require.NoError(t, err)
+	assert.Equal(t, 1000, opts.Customers)
+	assert.Equal(t, seed.DefaultMix(), opts.Mix)
+	assert.Equal(t, uint64(1), opts.Seed)
+	assert.Equal(t, now, opts.Now)
+	assert.Empty(t, opts.output)
+
+	opts, err = parseSeedFlags([]string{"-customers", "5", "-mix", "new=1,delinquent=2", "-seed", "9", "-as-of", "2025-01-06", "-out", "loans.txt"}, io.Discard, now)
+	require.NoError(t, err)
+	assert.Equal(t, 5, opts.Customers)
+	assert.Equal(t, seed.Mix{seed.StageNew: 1, seed.StageDelinquent: 2}, opts.Mix)
+	assert.Equal(t, uint64(9), opts.Seed)
+	assert.Equal(t, time.Date(2025, 1, 6, 12, 0, 0, 0, time.UTC), opts.Now)
+	assert.Equal(t, "loans.txt", opts.output)
+
+	for _, args := range [][]string{{"-customers", "0"}, {"-mix", "late=1"}, {"-mix", "new=0"}, {"-as-of", "06/01/2025"}} {
+		_, err := parseSeedFlags(args, io.Discard, now)
+		assert.Error(t, err, args)
+	}
+}
@@ -1031,7 +1031,7 @@ const docTemplate = `{
 var SwaggerInfo = &swag.Spec{
 	Version:          "1.0",
 	Host:             "",
-	BasePath:         "",
+	BasePath:         "/v1",
 	Schemes:          []string{},
 	Title:            "Billing Engine API",
 	Description:      "This is the API documentation for the Billing Engine service.",
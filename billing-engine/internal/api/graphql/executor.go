@@ -0,0 +1,366 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+type ResolveFunc func(ctx context.Context, source any, args map[string]any) (any, error)
+
+// Field declares a schema field. Type is a type name such as "String" or
+// "Loan", wrapped in brackets for lists. A trailing "!" on an argument type
+// marks the argument as required.
+type Field struct {
+	Type    string
+	Args    map[string]string
+	Resolve ResolveFunc
+}
+
+type Object struct {
+	Name   string
+	Fields map[string]*Field
+}
+
+type Schema struct {
+	query   *Object
+	objects map[string]*Object
+	limits  Limits
+
+	// presentError turns a resolver error into the message returned to the
+	// client.
+	presentError func(context.Context, error) string
+}
+
+// Limits bounds the queries a schema accepts. A zero field disables that
+// check.
+type Limits struct {
+	// MaxDepth caps how deeply selections nest; top-level fields are at
+	// depth 1.
+	MaxDepth int
+	// MaxNodes caps the number of fields a query may resolve. List fields
+	// count once per expected element: the value of their "first" argument
+	// when given, ListSize otherwise.
+	MaxNodes int
+	ListSize int
+}
+
+var scalars = map[string]bool{"String": true, "Int": true, "Float": true, "Boolean": true, "ID": true}
+
+func NewSchema(query *Object, types ...*Object) *Schema {
+	s := &Schema{
+		query:        query,
+		objects:      map[string]*Object{query.Name: query},
+		presentError: func(_ context.Context, err error) string { return err.Error() },
+	}
+	for _, t := range types {
+		s.objects[t.Name] = t
+	}
+	return s
+}
+
+// SetLimits replaces the query limits, which are off by default.
+func (s *Schema) SetLimits(l Limits) {
+	s.limits = l
+}
+
+type Error struct {
+	Message string `json:"message"`
+	Path    []any  `json:"path,omitempty"`
+}
+
+type Result struct {
+	Data   *OrderedMap `json:"data"`
+	Errors []Error     `json:"errors,omitempty"`
+}
+
+// OrderedMap keeps response keys in selection order, as the spec requires.
+type OrderedMap struct {
+	keys   []string
+	values map[string]any
+}
+
+func newOrderedMap() *OrderedMap {
+	return &OrderedMap{values: map[string]any{}}
+}
+
+func (m *OrderedMap) set(key string, v any) {
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = v
+}
+
+func (m *OrderedMap) Get(key string) (any, bool) {
+	v, ok := m.values[key]
+	return v, ok
+}
+
+func (m *OrderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(k)
+		buf.Write(key)
+		buf.WriteByte(':')
+		v, err := json.Marshal(m.values[k])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// Execute parses, validates and runs a query document. Validation failures
+// return errors without data; resolver failures null the field and are
+// reported with their path.
+func (s *Schema) Execute(ctx context.Context, query string, variables map[string]any, operationName string) *Result {
+	doc, err := parse(query)
+	if err != nil {
+		return &Result{Errors: []Error{{Message: "syntax error: " + err.Error()}}}
+	}
+
+	op, err := selectOperation(doc, operationName)
+	if err != nil {
+		return &Result{Errors: []Error{{Message: err.Error()}}}
+	}
+
+	if errs := s.validate(s.query, op.selection, nil); len(errs) > 0 {
+		return &Result{Errors: errs}
+	}
+
+	vars := make(map[string]any, len(op.variables))
+	for name, def := range op.variables {
+		vars[name] = def
+		if v, ok := variables[name]; ok {
+			vars[name] = v
+		}
+	}
+
+	if err := s.checkLimits(op.selection, vars); err != nil {
+		return &Result{Errors: []Error{{Message: err.Error()}}}
+	}
+
+	e := &execution{schema: s, variables: vars}
+	data := e.resolveObject(ctx, s.query, nil, op.selection, nil)
+	return &Result{Data: data, Errors: e.errors}
+}
+
+func selectOperation(doc *document, name string) (*operation, error) {
+	if name == "" {
+		if len(doc.operations) > 1 {
+			return nil, fmt.Errorf("operationName is required when the document contains several operations")
+		}
+		return doc.operations[0], nil
+	}
+	for _, op := range doc.operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation %q", name)
+}
+
+func (s *Schema) validate(obj *Object, selection []*field, path []any) []Error {
+	var errs []Error
+	for _, f := range selection {
+		fieldPath := appendPath(path, f.responseKey())
+		if f.name == "__typename" {
+			continue
+		}
+		def, ok := obj.Fields[f.name]
+		if !ok {
+			errs = append(errs, Error{Message: fmt.Sprintf("cannot query field %q on type %q", f.name, obj.Name), Path: fieldPath})
+			continue
+		}
+
+		for arg := range f.arguments {
+			if _, ok := def.Args[arg]; !ok {
+				errs = append(errs, Error{Message: fmt.Sprintf("unknown argument %q on field %q", arg, f.name), Path: fieldPath})
+			}
+		}
+		for arg, typ := range def.Args {
+			if _, ok := f.arguments[arg]; !ok && strings.HasSuffix(typ, "!") {
+				errs = append(errs, Error{Message: fmt.Sprintf("field %q argument %q of type %q is required", f.name, arg, typ), Path: fieldPath})
+			}
+		}
+
+		named := namedType(def.Type)
+		switch {
+		case scalars[named] && f.selection != nil:
+			errs = append(errs, Error{Message: fmt.Sprintf("field %q of type %q must not have a selection", f.name, def.Type), Path: fieldPath})
+		case !scalars[named] && f.selection == nil:
+			errs = append(errs, Error{Message: fmt.Sprintf("field %q of type %q must have a selection of subfields", f.name, def.Type), Path: fieldPath})
+		case !scalars[named]:
+			child, ok := s.objects[named]
+			if !ok {
+				errs = append(errs, Error{Message: fmt.Sprintf("unknown type %q", named), Path: fieldPath})
+				continue
+			}
+			errs = append(errs, s.validate(child, f.selection, fieldPath)...)
+		}
+	}
+	return errs
+}
+
+// checkLimits rejects a validated selection that nests deeper than MaxDepth
+// or would resolve more than MaxNodes fields, before any resolver runs.
+func (s *Schema) checkLimits(selection []*field, vars map[string]any) error {
+	if depth := selectionDepth(selection); s.limits.MaxDepth > 0 && depth > s.limits.MaxDepth {
+		return fmt.Errorf("query depth %d exceeds the limit of %d", depth, s.limits.MaxDepth)
+	}
+	if s.limits.MaxNodes > 0 {
+		if nodes := s.countNodes(s.query, selection, vars, s.limits.MaxNodes); nodes > s.limits.MaxNodes {
+			return fmt.Errorf("query would resolve more than %d fields", s.limits.MaxNodes)
+		}
+	}
+	return nil
+}
+
+func selectionDepth(selection []*field) int {
+	depth := 0
+	for _, f := range selection {
+		depth = max(depth, 1+selectionDepth(f.selection))
+	}
+	return depth
+}
+
+// countNodes estimates the fields a selection resolves on obj, stopping
+// once the count passes budget so that huge list multipliers cannot
+// overflow.
+func (s *Schema) countNodes(obj *Object, selection []*field, vars map[string]any, budget int) int {
+	nodes := 0
+	for _, f := range selection {
+		if nodes > budget {
+			break
+		}
+		if f.name == "__typename" {
+			nodes++
+			continue
+		}
+		def := obj.Fields[f.name]
+		per := 1
+		if child, ok := s.objects[namedType(def.Type)]; ok {
+			per += s.countNodes(child, f.selection, vars, budget)
+		}
+		if strings.HasPrefix(def.Type, "[") {
+			size := s.listSize(f, vars)
+			if size > 0 && per > (budget-nodes)/size {
+				return budget + 1
+			}
+			per *= size
+		}
+		nodes += per
+	}
+	return nodes
+}
+
+// listSize is the number of elements a list field is expected to return.
+func (s *Schema) listSize(f *field, vars map[string]any) int {
+	arg, ok := f.arguments["first"]
+	if !ok {
+		return s.limits.ListSize
+	}
+	v := arg.literal
+	if arg.variable != "" {
+		v = vars[arg.variable]
+	}
+	switch n := v.(type) {
+	case int64:
+		return int(min(max(n, 0), math.MaxInt32))
+	case float64:
+		return int(min(max(n, 0), math.MaxInt32))
+	}
+	return s.limits.ListSize
+}
+
+type execution struct {
+	schema    *Schema
+	variables map[string]any
+	errors    []Error
+}
+
+func (e *execution) resolveObject(ctx context.Context, obj *Object, source any, selection []*field, path []any) *OrderedMap {
+	out := newOrderedMap()
+	for _, f := range selection {
+		key := f.responseKey()
+		if f.name == "__typename" {
+			out.set(key, obj.Name)
+			continue
+		}
+		def := obj.Fields[f.name]
+		fieldPath := appendPath(path, key)
+
+		args := make(map[string]any, len(f.arguments))
+		for name, v := range f.arguments {
+			if v.variable != "" {
+				args[name] = e.variables[v.variable]
+			} else {
+				args[name] = v.literal
+			}
+		}
+
+		resolved, err := def.Resolve(ctx, source, args)
+		if err != nil {
+			e.errors = append(e.errors, Error{Message: e.schema.presentError(ctx, err), Path: fieldPath})
+			out.set(key, nil)
+			continue
+		}
+		out.set(key, e.complete(ctx, def.Type, resolved, f.selection, fieldPath))
+	}
+	return out
+}
+
+func (e *execution) complete(ctx context.Context, typ string, v any, selection []*field, path []any) any {
+	if isNil(v) {
+		return nil
+	}
+	if strings.HasPrefix(typ, "[") {
+		inner := strings.TrimSuffix(strings.TrimPrefix(typ, "["), "]")
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Slice {
+			e.errors = append(e.errors, Error{Message: fmt.Sprintf("expected a list for type %s", typ), Path: path})
+			return nil
+		}
+		items := make([]any, rv.Len())
+		for i := range items {
+			items[i] = e.complete(ctx, inner, rv.Index(i).Interface(), selection, appendPath(path, i))
+		}
+		return items
+	}
+	if obj, ok := e.schema.objects[typ]; ok {
+		return e.resolveObject(ctx, obj, v, selection, path)
+	}
+	return v
+}
+
+func namedType(typ string) string {
+	return strings.Trim(typ, "[]!")
+}
+
+func appendPath(path []any, elem any) []any {
+	out := make([]any, len(path), len(path)+1)
+	copy(out, path)
+	return append(out, elem)
+}
+
+func isNil(v any) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Map, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testItem struct {
+	Name string
+	Tags []string
+}
+
+func testSchema() *Schema {
+	item := &Object{Name: "Item", Fields: map[string]*Field{
+		"name": {Type: "String", Resolve: func(_ context.Context, src any, _ map[string]any) (any, error) {
+			return src.(testItem).Name, nil
+		}},
+		"tags": {Type: "[String]", Resolve: func(_ context.Context, src any, _ map[string]any) (any, error) {
+			return src.(testItem).Tags, nil
+		}},
+		"broken": {Type: "String", Resolve: func(context.Context, any, map[string]any) (any, error) {
+			return nil, errors.New("boom")
+		}},
+	}}
+	query := &Object{Name: "Query", Fields: map[string]*Field{
+		"item": {Type: "Item", Args: map[string]string{"name": "String!"}, Resolve: func(_ context.Context, _ any, args map[string]any) (any, error) {
+			return testItem{Name: args["name"].(string), Tags: []string{"a", "b"}}, nil
+		}},
+		"items": {Type: "[Item]", Args: map[string]string{"first": "Int"}, Resolve: func(context.Context, any, map[string]any) (any, error) {
+			return []testItem{{Name: "x"}, {Name: "y"}}, nil
+		}},
+	}}
+	return NewSchema(query, item)
+}
+
+func marshal(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestExecute(t *testing.T) {
+	res := testSchema().Execute(context.Background(), `query Q($n: String!) {
+		first: item(name: $n) { tags name __typename }
+		items { name }
+	}`, map[string]any{"n": "widget"}, "")
+
+	assert.Empty(t, res.Errors)
+	assert.Equal(t,
+		`{"first":{"tags":["a","b"],"name":"widget","__typename":"Item"},"items":[{"name":"x"},{"name":"y"}]}`,
+		marshal(t, res.Data))
+}
+
+func TestExecuteResolverError(t *testing.T) {
+	res := testSchema().Execute(context.Background(), `{ items { name broken } }`, nil, "")
+
+	require.Len(t, res.Errors, 2)
+	assert.Equal(t, "boom", res.Errors[0].Message)
+	assert.Equal(t, []any{"items", 0, "broken"}, res.Errors[0].Path)
+	assert.Equal(t, `{"items":[{"name":"x","broken":null},{"name":"y","broken":null}]}`, marshal(t, res.Data))
+}
+
+func TestExecuteValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"Unknown field", `{ items { price } }`, `cannot query field "price" on type "Item"`},
+		{"Missing required argument", `{ item { name } }`, `argument "name" of type "String!" is required`},
+		{"Unknown argument", `{ items(limit: 1) { name } }`, `unknown argument "limit"`},
+		{"Missing selection", `{ items }`, "must have a selection of subfields"},
+		{"Selection on scalar", `{ items { name { length } } }`, "must not have a selection"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := testSchema().Execute(context.Background(), tt.query, nil, "")
+			assert.Nil(t, res.Data)
+			require.NotEmpty(t, res.Errors)
+			assert.Contains(t, res.Errors[0].Message, tt.want)
+		})
+	}
+}
+
+func TestExecuteOperationSelection(t *testing.T) {
+	doc := `query A { items { name } } query B { item(name: "b") { name } }`
+
+	res := testSchema().Execute(context.Background(), doc, nil, "B")
+	assert.Empty(t, res.Errors)
+	assert.Equal(t, `{"item":{"name":"b"}}`, marshal(t, res.Data))
+
+	res = testSchema().Execute(context.Background(), doc, nil, "")
+	require.Len(t, res.Errors, 1)
+	assert.Contains(t, res.Errors[0].Message, "operationName is required")
+
+	res = testSchema().Execute(context.Background(), `{ items {`, nil, "")
+	require.Len(t, res.Errors, 1)
+	assert.Contains(t, res.Errors[0].Message, "syntax error")
+}
+
+func TestExecuteLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits Limits
+		query  string
+		vars   map[string]any
+		want   string
+	}{
+		{"Too deep", Limits{MaxDepth: 1}, `{ item(name: "a") { name } }`, nil, "query depth 2 exceeds the limit of 1"},
+		{"Lists count their expected size", Limits{MaxNodes: 10, ListSize: 5}, `{ items { name tags } }`, nil, "more than 10 fields"},
+		{"First from a variable", Limits{MaxNodes: 10, ListSize: 1}, `query($n: Int) { items(first: $n) { name } }`, map[string]any{"n": float64(100)}, "more than 10 fields"},
+		{"Huge first does not overflow", Limits{MaxNodes: 10}, `{ items(first: 2147483647) { tags } }`, nil, "more than 10 fields"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := testSchema()
+			s.SetLimits(tt.limits)
+			res := s.Execute(context.Background(), tt.query, tt.vars, "")
+			assert.Nil(t, res.Data)
+			require.Len(t, res.Errors, 1)
+			assert.Contains(t, res.Errors[0].Message, tt.want)
+		})
+	}
+
+	t.Run("Within the limits", func(t *testing.T) {
+		s := testSchema()
+		s.SetLimits(Limits{MaxDepth: 2, MaxNodes: 10, ListSize: 5})
+		res := s.Execute(context.Background(), `{ items(first: 2) { name } }`, nil, "")
+		assert.Empty(t, res.Errors)
+		assert.Equal(t, `{"items":[{"name":"x"},{"name":"y"}]}`, marshal(t, res.Data))
+	})
+}
@@ -0,0 +1,406 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// The parser covers the read-only subset the dashboards need: query
+// operations, aliases, arguments, variables and nested selections.
+// Fragments, directives, mutations and subscriptions are rejected.
+
+type document struct {
+	operations []*operation
+}
+
+type operation struct {
+	name      string
+	variables map[string]any
+	selection []*field
+}
+
+type field struct {
+	alias     string
+	name      string
+	arguments map[string]value
+	selection []*field
+}
+
+func (f *field) responseKey() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+// value is a literal argument or a reference to an operation variable.
+type value struct {
+	variable string
+	literal  any
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunct
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	runes := []rune(src)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r) || r == ',' || r == '\uFEFF':
+			i++
+		case r == '#':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case strings.ContainsRune("!$():=@[]{}|", r):
+			tokens = append(tokens, token{kind: tokenPunct, text: string(r), pos: i})
+			i++
+		case r == '.':
+			if i+2 >= len(runes) || runes[i+1] != '.' || runes[i+2] != '.' {
+				return nil, fmt.Errorf("unexpected character '.' at position %d", i)
+			}
+			tokens = append(tokens, token{kind: tokenPunct, text: "...", pos: i})
+			i += 3
+		case r == '_' || unicode.IsLetter(r):
+			start := i
+			for i < len(runes) && (runes[i] == '_' || unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenName, text: string(runes[start:i]), pos: start})
+		case r == '-' || unicode.IsDigit(r):
+			start := i
+			kind := tokenInt
+			i++
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || strings.ContainsRune(".eE+-", runes[i])) {
+				if !unicode.IsDigit(runes[i]) {
+					kind = tokenFloat
+				}
+				i++
+			}
+			tokens = append(tokens, token{kind: kind, text: string(runes[start:i]), pos: start})
+		case r == '"':
+			start := i
+			i++
+			var sb strings.Builder
+			for {
+				if i >= len(runes) || runes[i] == '\n' {
+					return nil, fmt.Errorf("unterminated string at position %d", start)
+				}
+				if runes[i] == '"' {
+					i++
+					break
+				}
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+					switch runes[i] {
+					case 'n':
+						sb.WriteRune('\n')
+					case 't':
+						sb.WriteRune('\t')
+					default:
+						sb.WriteRune(runes[i])
+					}
+					i++
+					continue
+				}
+				sb.WriteRune(runes[i])
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenString, text: sb.String(), pos: start})
+		default:
+			return nil, fmt.Errorf("unexpected character %q at position %d", r, i)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(runes)}), nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func parse(src string) (*document, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+
+	doc := &document{}
+	for p.peek().kind != tokenEOF {
+		op, err := p.parseOperation()
+		if err != nil {
+			return nil, err
+		}
+		doc.operations = append(doc.operations, op)
+	}
+	if len(doc.operations) == 0 {
+		return nil, fmt.Errorf("document does not contain an operation")
+	}
+	return doc, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isPunct(text string) bool {
+	t := p.peek()
+	return t.kind == tokenPunct && t.text == text
+}
+
+func (p *parser) expect(text string) error {
+	t := p.next()
+	if t.kind != tokenPunct || t.text != text {
+		return fmt.Errorf("expected %q at position %d, found %q", text, t.pos, t.text)
+	}
+	return nil
+}
+
+func (p *parser) expectName() (string, error) {
+	t := p.next()
+	if t.kind != tokenName {
+		return "", fmt.Errorf("expected name at position %d, found %q", t.pos, t.text)
+	}
+	return t.text, nil
+}
+
+func (p *parser) parseOperation() (*operation, error) {
+	op := &operation{variables: map[string]any{}}
+	if p.isPunct("{") {
+		sel, err := p.parseSelectionSet()
+		op.selection = sel
+		return op, err
+	}
+
+	keyword, err := p.expectName()
+	if err != nil {
+		return nil, err
+	}
+	switch keyword {
+	case "query":
+	case "mutation", "subscription":
+		return nil, fmt.Errorf("%s operations are not supported, the API is read-only", keyword)
+	case "fragment":
+		return nil, fmt.Errorf("fragments are not supported")
+	default:
+		return nil, fmt.Errorf("unexpected %q, expected an operation", keyword)
+	}
+
+	if p.peek().kind == tokenName {
+		op.name = p.next().text
+	}
+	if p.isPunct("(") {
+		if err := p.parseVariableDefinitions(op); err != nil {
+			return nil, err
+		}
+	}
+	if p.isPunct("@") {
+		return nil, fmt.Errorf("directives are not supported")
+	}
+	op.selection, err = p.parseSelectionSet()
+	return op, err
+}
+
+// parseVariableDefinitions records declared variables with their default
+// values. Declared types are parsed but not enforced; resolvers coerce.
+func (p *parser) parseVariableDefinitions(op *operation) error {
+	p.next()
+	for !p.isPunct(")") {
+		if err := p.expect("$"); err != nil {
+			return err
+		}
+		name, err := p.expectName()
+		if err != nil {
+			return err
+		}
+		if err := p.expect(":"); err != nil {
+			return err
+		}
+		if err := p.skipType(); err != nil {
+			return err
+		}
+		op.variables[name] = nil
+		if p.isPunct("=") {
+			p.next()
+			def, err := p.parseValue()
+			if err != nil {
+				return err
+			}
+			op.variables[name] = def.literal
+		}
+		if p.peek().kind == tokenEOF {
+			return fmt.Errorf("unterminated variable definitions")
+		}
+	}
+	p.next()
+	return nil
+}
+
+func (p *parser) skipType() error {
+	if p.isPunct("[") {
+		p.next()
+		if err := p.skipType(); err != nil {
+			return err
+		}
+		if err := p.expect("]"); err != nil {
+			return err
+		}
+	} else if _, err := p.expectName(); err != nil {
+		return err
+	}
+	if p.isPunct("!") {
+		p.next()
+	}
+	return nil
+}
+
+func (p *parser) parseSelectionSet() ([]*field, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var fields []*field
+	for !p.isPunct("}") {
+		if p.isPunct("...") {
+			return nil, fmt.Errorf("fragments are not supported")
+		}
+		if p.peek().kind == tokenEOF {
+			return nil, fmt.Errorf("unterminated selection set")
+		}
+		f, err := p.parseField()
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, f)
+	}
+	p.next()
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("selection set cannot be empty")
+	}
+	return fields, nil
+}
+
+func (p *parser) parseField() (*field, error) {
+	name, err := p.expectName()
+	if err != nil {
+		return nil, err
+	}
+	f := &field{name: name}
+	if p.isPunct(":") {
+		p.next()
+		f.alias = name
+		if f.name, err = p.expectName(); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.isPunct("(") {
+		p.next()
+		f.arguments = map[string]value{}
+		for !p.isPunct(")") {
+			argName, err := p.expectName()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			v, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			f.arguments[argName] = v
+		}
+		p.next()
+	}
+
+	if p.isPunct("@") {
+		return nil, fmt.Errorf("directives are not supported")
+	}
+	if p.isPunct("{") {
+		if f.selection, err = p.parseSelectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+func (p *parser) parseValue() (value, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenPunct:
+		switch t.text {
+		case "$":
+			name, err := p.expectName()
+			return value{variable: name}, err
+		case "[":
+			var list []any
+			for !p.isPunct("]") {
+				if p.peek().kind == tokenEOF {
+					return value{}, fmt.Errorf("unterminated list value")
+				}
+				item, err := p.parseValue()
+				if err != nil {
+					return value{}, err
+				}
+				if item.variable != "" {
+					return value{}, fmt.Errorf("variables inside list values are not supported")
+				}
+				list = append(list, item.literal)
+			}
+			p.next()
+			return value{literal: list}, nil
+		}
+	case tokenInt:
+		n, err := strconv.ParseInt(t.text, 10, 64)
+		if err != nil {
+			return value{}, fmt.Errorf("invalid integer %q", t.text)
+		}
+		return value{literal: n}, nil
+	case tokenFloat:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return value{}, fmt.Errorf("invalid float %q", t.text)
+		}
+		return value{literal: f}, nil
+	case tokenString:
+		return value{literal: t.text}, nil
+	case tokenName:
+		switch t.text {
+		case "true":
+			return value{literal: true}, nil
+		case "false":
+			return value{literal: false}, nil
+		case "null":
+			return value{}, nil
+		}
+		return value{literal: t.text}, nil
+	}
+	return value{}, fmt.Errorf("unexpected %q at position %d, expected a value", t.text, t.pos)
+}
@@ -0,0 +1,67 @@
+package graphql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	doc, err := parse(`
+		# dashboard query
+		query CustomerView($id: ID!, $withSchedule: Boolean = true) {
+			c: customer(id: $id) {
+				name
+				loan { id schedule { weekNumber } }
+			}
+			loan(id: 42) { status }
+		}`)
+	require.NoError(t, err)
+	require.Len(t, doc.operations, 1)
+
+	op := doc.operations[0]
+	assert.Equal(t, "CustomerView", op.name)
+	assert.Equal(t, map[string]any{"id": nil, "withSchedule": true}, op.variables)
+	require.Len(t, op.selection, 2)
+
+	cust := op.selection[0]
+	assert.Equal(t, "c", cust.responseKey())
+	assert.Equal(t, "customer", cust.name)
+	assert.Equal(t, value{variable: "id"}, cust.arguments["id"])
+	require.Len(t, cust.selection, 2)
+	assert.Equal(t, "loan", cust.selection[1].name)
+	assert.Equal(t, "schedule", cust.selection[1].selection[1].name)
+
+	assert.Equal(t, value{literal: int64(42)}, op.selection[1].arguments["id"])
+}
+
+func TestParseShorthandQuery(t *testing.T) {
+	doc, err := parse(`{ customers { name } }`)
+	require.NoError(t, err)
+	assert.Equal(t, "customers", doc.operations[0].selection[0].name)
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"Mutation", `mutation { makePayment(id: 1) }`, "not supported"},
+		{"Fragment", `{ customer(id: 1) { ...CustomerFields } }`, "fragments are not supported"},
+		{"Directive", `{ customer(id: 1) @include(if: true) { name } }`, "directives are not supported"},
+		{"Unterminated", `{ customer(id: 1) { name }`, "unterminated"},
+		{"Empty selection", `{ }`, "cannot be empty"},
+		{"Bad string", `{ customer(id: "1) { name } }`, "unterminated string"},
+		{"Empty document", `  `, "does not contain an operation"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parse(tt.query)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
@@ -0,0 +1,328 @@
+package graphql
+
+import (
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/pkg/i18n"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Payment is a payment from the loan's ledger, with the week of the
+// installment it paid. WeekNumber is nil for a payment that settled a fee.
+type Payment struct {
+	loan.Payment
+	WeekNumber *int
+}
+
+const (
+	// maxQueryDepth allows customer -> loan -> schedule and one more level
+	// back through loan.customer, but not unbounded cycles.
+	maxQueryDepth = 6
+	// maxQueryNodes bounds the fields one query resolves, which also
+	// bounds the per-customer loan lookups a customers query can fan out
+	// to.
+	maxQueryNodes = 10000
+	// expectedListSize is what a list without "first" is assumed to hold:
+	// a year of weekly schedule entries.
+	expectedListSize = 52
+
+	// defaultPageSize and maxPageSize bound the customers page.
+	defaultPageSize = 50
+	maxPageSize     = 100
+)
+
+// NewBillingSchema builds the read-only schema over customers, loans,
+// schedules and payments. Resolvers go through the domain services so the
+// same authorization and not-found handling as the REST API applies.
+func NewBillingSchema(loanService loan.LoanService, customerService customer.CustomerService) *Schema {
+	loanByID := func(ctx context.Context, id int64) (any, error) {
+		l, err := loanService.GetLoan(ctx, id, true)
+		if err != nil {
+			return nil, err
+		}
+		return l, nil
+	}
+
+	customerType := &Object{Name: "Customer", Fields: map[string]*Field{
+		"customerId":   {Type: "ID", Resolve: customerField(func(c *customer.Customer) any { return strconv.FormatInt(c.CustomerID, 10) })},
+		"publicId":     {Type: "ID", Resolve: customerField(func(c *customer.Customer) any { return optionalUUID(c.PublicID) })},
+		"name":         {Type: "String", Resolve: customerField(func(c *customer.Customer) any { return c.Name })},
+		"address":      {Type: "String", Resolve: customerField(func(c *customer.Customer) any { return c.Address })},
+		"isDelinquent": {Type: "Boolean", Resolve: customerField(func(c *customer.Customer) any { return c.IsDelinquent })},
+		"active":       {Type: "Boolean", Resolve: customerField(func(c *customer.Customer) any { return c.Active })},
+		"createDate":   {Type: "String", Resolve: customerField(func(c *customer.Customer) any { return formatTime(c.CreateDate) })},
+		"updatedAt":    {Type: "String", Resolve: customerField(func(c *customer.Customer) any { return formatTime(c.UpdatedAt) })},
+		"loanId": {Type: "ID", Resolve: customerField(func(c *customer.Customer) any {
+			if c.LoanID == nil {
+				return nil
+			}
+			return strconv.FormatInt(*c.LoanID, 10)
+		})},
+		"externalRef": {Type: "String", Resolve: customerField(func(c *customer.Customer) any { return optionalString(c.ExternalRef) })},
+		"loan": {Type: "Loan", Resolve: func(ctx context.Context, source any, _ map[string]any) (any, error) {
+			c := source.(*customer.Customer)
+			if c.LoanID == nil {
+				return nil, nil
+			}
+			return loanByID(ctx, *c.LoanID)
+		}},
+	}}
+
+	loanType := &Object{Name: "Loan", Fields: map[string]*Field{
+		"id":                  {Type: "ID", Resolve: loanField(func(l *loan.Loan) any { return strconv.FormatInt(l.ID, 10) })},
+		"publicId":            {Type: "ID", Resolve: loanField(func(l *loan.Loan) any { return optionalUUID(l.PublicID) })},
+		"principalAmount":     {Type: "String", Resolve: loanField(func(l *loan.Loan) any { return formatMoney(l.PrincipalAmount) })},
+		"interestRate":        {Type: "String", Resolve: loanField(func(l *loan.Loan) any { return decimal.NewFromFloat(l.InterestRate).String() })},
+		"termWeeks":           {Type: "Int", Resolve: loanField(func(l *loan.Loan) any { return l.TermWeeks })},
+		"weeklyPaymentAmount": {Type: "String", Resolve: loanField(func(l *loan.Loan) any { return formatMoney(l.WeeklyPaymentAmount) })},
+		"totalLoanAmount":     {Type: "String", Resolve: loanField(func(l *loan.Loan) any { return formatMoney(l.TotalLoanAmount) })},
+		"startDate":           {Type: "String", Resolve: loanField(func(l *loan.Loan) any { return l.StartDate.Format(time.DateOnly) })},
+		"status":              {Type: "String", Resolve: loanField(func(l *loan.Loan) any { return string(l.Status) })},
+		"daysPastDue":         {Type: "Int", Resolve: loanField(func(l *loan.Loan) any { return l.DaysPastDue })},
+		"externalRef":         {Type: "String", Resolve: loanField(func(l *loan.Loan) any { return optionalString(l.ExternalRef) })},
+		"createdAt":           {Type: "String", Resolve: loanField(func(l *loan.Loan) any { return formatTime(l.CreatedAt) })},
+		"updatedAt":           {Type: "String", Resolve: loanField(func(l *loan.Loan) any { return formatTime(l.UpdatedAt) })},
+		"schedule":            {Type: "[ScheduleEntry]", Resolve: loanField(func(l *loan.Loan) any { return l.Schedule })},
+		"payments": {Type: "[Payment]", Args: map[string]string{"first": "Int"}, Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+			first, err := pageSizeArg(args, "first")
+			if err != nil {
+				return nil, err
+			}
+			l := source.(*loan.Loan)
+			payments, err := loanService.ListPayments(ctx, l.ID, first)
+			if err != nil {
+				return nil, err
+			}
+			return paymentsOf(payments, l.Schedule), nil
+		}},
+		"outstandingAmount": {Type: "String", Resolve: func(ctx context.Context, source any, _ map[string]any) (any, error) {
+			amount, err := loanService.GetOutstanding(ctx, source.(*loan.Loan).ID)
+			if err != nil {
+				return nil, err
+			}
+			return formatMoney(amount), nil
+		}},
+		"isDelinquent": {Type: "Boolean", Resolve: func(ctx context.Context, source any, _ map[string]any) (any, error) {
+			return loanService.IsDelinquent(ctx, source.(*loan.Loan).ID)
+		}},
+		"customer": {Type: "Customer", Resolve: func(ctx context.Context, source any, _ map[string]any) (any, error) {
+			return customerService.FindCustomerByLoan(ctx, source.(*loan.Loan).ID)
+		}},
+	}}
+
+	scheduleType := &Object{Name: "ScheduleEntry", Fields: map[string]*Field{
+		"id":          {Type: "ID", Resolve: scheduleField(func(e loan.ScheduleEntry) any { return strconv.FormatInt(e.ID, 10) })},
+		"weekNumber":  {Type: "Int", Resolve: scheduleField(func(e loan.ScheduleEntry) any { return e.WeekNumber })},
+		"dueDate":     {Type: "String", Resolve: scheduleField(func(e loan.ScheduleEntry) any { return e.DueDate.Format(time.DateOnly) })},
+		"dueAmount":   {Type: "String", Resolve: scheduleField(func(e loan.ScheduleEntry) any { return formatMoney(e.DueAmount) })},
+		"paidAmount":  {Type: "String", Resolve: scheduleField(func(e loan.ScheduleEntry) any { return formatMoney(e.PaidAmount) })},
+		"paymentDate": {Type: "String", Resolve: scheduleField(func(e loan.ScheduleEntry) any { return formatTimePtr(e.PaymentDate) })},
+		"status":      {Type: "String", Resolve: scheduleField(func(e loan.ScheduleEntry) any { return string(e.Status) })},
+	}}
+
+	paymentType := &Object{Name: "Payment", Fields: map[string]*Field{
+		"id":         {Type: "ID", Resolve: paymentField(func(p Payment) any { return strconv.FormatInt(p.ID, 10) })},
+		"scheduleId": {Type: "ID", Resolve: paymentField(func(p Payment) any { return optionalID(p.ScheduleID) })},
+		"feeId":      {Type: "ID", Resolve: paymentField(func(p Payment) any { return optionalID(p.FeeID) })},
+		"weekNumber": {Type: "Int", Resolve: paymentField(func(p Payment) any {
+			if p.WeekNumber == nil {
+				return nil
+			}
+			return *p.WeekNumber
+		})},
+		"amount":      {Type: "String", Resolve: paymentField(func(p Payment) any { return formatMoney(p.Amount) })},
+		"channel":     {Type: "String", Resolve: paymentField(func(p Payment) any { return string(p.Channel) })},
+		"reference":   {Type: "String", Resolve: paymentField(func(p Payment) any { return optionalString(p.Reference) })},
+		"paymentDate": {Type: "String", Resolve: paymentField(func(p Payment) any { return formatTime(p.PaidAt) })},
+	}}
+
+	query := &Object{Name: "Query", Fields: map[string]*Field{
+		"customer": {Type: "Customer", Args: map[string]string{"id": "ID!"}, Resolve: func(ctx context.Context, _ any, args map[string]any) (any, error) {
+			id, err := idArg(args, "id")
+			if err != nil {
+				return nil, err
+			}
+			return customerService.GetCustomer(ctx, id)
+		}},
+		"customers": {Type: "[Customer]", Args: map[string]string{"first": "Int", "after": "ID"}, Resolve: func(ctx context.Context, _ any, args map[string]any) (any, error) {
+			first, err := pageSizeArg(args, "first")
+			if err != nil {
+				return nil, err
+			}
+			var after int64
+			if args["after"] != nil {
+				if after, err = idArg(args, "after"); err != nil {
+					return nil, err
+				}
+			}
+			return customerService.ListActiveCustomersPage(ctx, after, first)
+		}},
+		"customerByLoan": {Type: "Customer", Args: map[string]string{"loanId": "ID!"}, Resolve: func(ctx context.Context, _ any, args map[string]any) (any, error) {
+			id, err := idArg(args, "loanId")
+			if err != nil {
+				return nil, err
+			}
+			return customerService.FindCustomerByLoan(ctx, id)
+		}},
+		"loan": {Type: "Loan", Args: map[string]string{"id": "ID!"}, Resolve: func(ctx context.Context, _ any, args map[string]any) (any, error) {
+			id, err := idArg(args, "id")
+			if err != nil {
+				return nil, err
+			}
+			return loanByID(ctx, id)
+		}},
+	}}
+
+	schema := NewSchema(query, customerType, loanType, scheduleType, paymentType)
+	schema.presentError = presentError
+	schema.SetLimits(Limits{MaxDepth: maxQueryDepth, MaxNodes: maxQueryNodes, ListSize: expectedListSize})
+	return schema
+}
+
+// presentError hides database and internal failures behind a generic message,
+// matching what respondError does for the REST handlers, in the request's
+// locale.
+func presentError(ctx context.Context, err error) string {
+	var argErr *argumentError
+	if errors.As(err, &argErr) {
+		return argErr.Error()
+	}
+	return i18n.ErrorMessage(i18n.FromContext(ctx), err)
+}
+
+type argumentError struct {
+	name string
+	rule string
+}
+
+func (e *argumentError) Error() string {
+	return fmt.Sprintf("argument %q %s", e.name, e.rule)
+}
+
+func customerField(get func(*customer.Customer) any) ResolveFunc {
+	return func(_ context.Context, source any, _ map[string]any) (any, error) {
+		return get(source.(*customer.Customer)), nil
+	}
+}
+
+func loanField(get func(*loan.Loan) any) ResolveFunc {
+	return func(_ context.Context, source any, _ map[string]any) (any, error) {
+		return get(source.(*loan.Loan)), nil
+	}
+}
+
+func scheduleField(get func(loan.ScheduleEntry) any) ResolveFunc {
+	return func(_ context.Context, source any, _ map[string]any) (any, error) {
+		return get(source.(loan.ScheduleEntry)), nil
+	}
+}
+
+func paymentField(get func(Payment) any) ResolveFunc {
+	return func(_ context.Context, source any, _ map[string]any) (any, error) {
+		return get(source.(Payment)), nil
+	}
+}
+
+// paymentsOf pairs the ledger's payments with the week of the installment
+// each one paid, looked up in the loan's schedule.
+func paymentsOf(ledger []loan.Payment, schedule []loan.ScheduleEntry) []Payment {
+	weeks := make(map[int64]int, len(schedule))
+	for _, entry := range schedule {
+		weeks[entry.ID] = entry.WeekNumber
+	}
+	payments := make([]Payment, 0, len(ledger))
+	for _, p := range ledger {
+		payment := Payment{Payment: p}
+		if p.ScheduleID != nil {
+			if week, ok := weeks[*p.ScheduleID]; ok {
+				payment.WeekNumber = &week
+			}
+		}
+		payments = append(payments, payment)
+	}
+	return payments
+}
+
+func idArg(args map[string]any, name string) (int64, error) {
+	var (
+		id  int64
+		err error
+	)
+	switch v := args[name].(type) {
+	case int64:
+		id = v
+	case float64:
+		id = int64(v)
+	case string:
+		id, err = strconv.ParseInt(v, 10, 64)
+	default:
+		err = fmt.Errorf("unsupported type %T", v)
+	}
+	if err != nil || id <= 0 {
+		return 0, &argumentError{name: name, rule: "must be a positive ID"}
+	}
+	return id, nil
+}
+
+// pageSizeArg reads an optional page size, defaulting to defaultPageSize.
+func pageSizeArg(args map[string]any, name string) (int, error) {
+	var n int64
+	switch v := args[name].(type) {
+	case nil:
+		return defaultPageSize, nil
+	case int64:
+		n = v
+	case float64:
+		n = int64(v)
+		if float64(n) != v {
+			n = 0
+		}
+	}
+	if n < 1 || n > maxPageSize {
+		return 0, &argumentError{name: name, rule: fmt.Sprintf("must be between 1 and %d", maxPageSize)}
+	}
+	return int(n), nil
+}
+
+func formatMoney(amount float64) string {
+	return decimal.NewFromFloat(amount).StringFixed(2)
+}
+
+func formatTime(t time.Time) string {
+	return t.Format(time.RFC3339)
+}
+
+func formatTimePtr(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return formatTime(*t)
+}
+
+func optionalString(s *string) any {
+	if s == nil {
+		return nil
+	}
+	return *s
+}
+
+func optionalID(id *int64) any {
+	if id == nil {
+		return nil
+	}
+	return strconv.FormatInt(*id, 10)
+}
+
+func optionalUUID(id uuid.UUID) any {
+	if id == uuid.Nil {
+		return nil
+	}
+	return id.String()
+}
@@ -0,0 +1,181 @@
+package graphql
+
+import (
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/i18n"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeLoanService struct {
+	loan.LoanService
+	loans    map[int64]*loan.Loan
+	payments map[int64][]loan.Payment
+}
+
+func (f *fakeLoanService) GetLoan(_ context.Context, loanID int64, _ bool) (*loan.Loan, error) {
+	l, ok := f.loans[loanID]
+	if !ok {
+		return nil, fmt.Errorf("%w: loan with ID %d not found", apperrors.ErrNotFound, loanID)
+	}
+	return l, nil
+}
+
+func (f *fakeLoanService) ListPayments(_ context.Context, loanID int64, limit int) ([]loan.Payment, error) {
+	payments := f.payments[loanID]
+	if len(payments) > limit {
+		payments = payments[:limit]
+	}
+	return payments, nil
+}
+
+func (f *fakeLoanService) GetOutstanding(_ context.Context, loanID int64) (float64, error) {
+	return 900, nil
+}
+
+type fakeCustomerService struct {
+	customer.CustomerService
+	customers map[int64]*customer.Customer
+	listErr   error
+}
+
+func (f *fakeCustomerService) GetCustomer(_ context.Context, customerID int64) (*customer.Customer, error) {
+	c, ok := f.customers[customerID]
+	if !ok {
+		return nil, customer.ErrNotFound
+	}
+	return c, nil
+}
+
+func (f *fakeCustomerService) ListActiveCustomersPage(_ context.Context, afterID int64, limit int) ([]*customer.Customer, error) {
+	if f.listErr != nil {
+		return nil, f.listErr
+	}
+	var page []*customer.Customer
+	for id := afterID + 1; len(page) < limit && id <= int64(len(f.customers)); id++ {
+		page = append(page, f.customers[id])
+	}
+	return page, nil
+}
+
+func billingFixture() *Schema {
+	loanID, scheduleID, feeID := int64(10), int64(1), int64(3)
+	paidAt := time.Date(2024, 1, 8, 10, 0, 0, 0, time.UTC)
+	feePaidAt := time.Date(2024, 1, 16, 9, 0, 0, 0, time.UTC)
+	loans := &fakeLoanService{loans: map[int64]*loan.Loan{
+		10: {
+			ID: 10, PrincipalAmount: 1000, InterestRate: 0.1, TermWeeks: 2,
+			WeeklyPaymentAmount: 550, TotalLoanAmount: 1100,
+			StartDate: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Status: loan.StatusActive,
+			Schedule: []loan.ScheduleEntry{
+				{ID: 1, WeekNumber: 1, DueAmount: 550, PaidAmount: 550, PaymentDate: &paidAt, Status: loan.PaymentStatusPaid},
+				{ID: 2, WeekNumber: 2, DueAmount: 550, Status: loan.PaymentStatusPending},
+			},
+		},
+	}, payments: map[int64][]loan.Payment{
+		10: {
+			{ID: 8, LoanID: 10, FeeID: &feeID, Amount: 27.75, Channel: loan.ChannelBankTransfer, PaidAt: feePaidAt},
+			{ID: 7, LoanID: 10, ScheduleID: &scheduleID, Amount: 550, Channel: loan.ChannelCash, PaidAt: paidAt},
+		},
+	}}
+	customers := &fakeCustomerService{customers: map[int64]*customer.Customer{
+		1: {CustomerID: 1, Name: "Jane", LoanID: &loanID, Active: true},
+	}, listErr: fmt.Errorf("%w: connection refused", apperrors.ErrDatabase)}
+	return NewBillingSchema(loans, customers)
+}
+
+func TestBillingSchemaNestedQuery(t *testing.T) {
+	res := billingFixture().Execute(context.Background(), `{
+		customer(id: "1") {
+			name
+			loan {
+				id principalAmount outstandingAmount
+				schedule { weekNumber status }
+				payments { id scheduleId feeId weekNumber amount channel paymentDate }
+			}
+		}
+	}`, nil, "")
+
+	require.Empty(t, res.Errors)
+	assert.JSONEq(t, `{"customer":{"name":"Jane","loan":{
+		"id":"10","principalAmount":"1000.00","outstandingAmount":"900.00",
+		"schedule":[{"weekNumber":1,"status":"PAID"},{"weekNumber":2,"status":"PENDING"}],
+		"payments":[
+			{"id":"8","scheduleId":null,"feeId":"3","weekNumber":null,"amount":"27.75","channel":"BANK_TRANSFER","paymentDate":"2024-01-16T09:00:00Z"},
+			{"id":"7","scheduleId":"1","feeId":null,"weekNumber":1,"amount":"550.00","channel":"CASH","paymentDate":"2024-01-08T10:00:00Z"}
+		]
+	}}}`, marshal(t, res.Data))
+}
+
+func TestBillingSchemaErrors(t *testing.T) {
+	res := billingFixture().Execute(context.Background(), `query($id: ID!) {
+		missing: customer(id: $id) { name }
+		loan(id: 99) { id }
+		customers { name }
+		bad: loan(id: -1) { id }
+	}`, map[string]any{"id": float64(5)}, "")
+
+	require.Len(t, res.Errors, 4)
+	messages := map[string]string{}
+	for _, e := range res.Errors {
+		messages[e.Path[0].(string)] = e.Message
+	}
+	assert.Equal(t, "Resource not found.", messages["missing"])
+	assert.Equal(t, "Resource not found.", messages["loan"])
+	assert.Equal(t, "An unexpected error occurred.", messages["customers"])
+	assert.Equal(t, `argument "id" must be a positive ID`, messages["bad"])
+	assert.Equal(t, `{"missing":null,"loan":null,"customers":null,"bad":null}`, marshal(t, res.Data))
+}
+
+func TestBillingSchemaErrorsLocalized(t *testing.T) {
+	ctx := i18n.WithLocale(context.Background(), i18n.Indonesian)
+	res := billingFixture().Execute(ctx, `{ loan(id: 99) { id } customers { name } }`, nil, "")
+
+	require.Len(t, res.Errors, 2)
+	assert.Equal(t, "Data tidak ditemukan.", res.Errors[0].Message)
+	assert.Equal(t, "Terjadi kesalahan yang tidak terduga.", res.Errors[1].Message)
+}
+
+func TestBillingSchemaCustomersPage(t *testing.T) {
+	customers := &fakeCustomerService{customers: map[int64]*customer.Customer{}}
+	for id := int64(1); id <= 120; id++ {
+		customers.customers[id] = &customer.Customer{CustomerID: id, Active: true}
+	}
+	schema := NewBillingSchema(&fakeLoanService{}, customers)
+
+	res := schema.Execute(context.Background(), `{ customers(first: 2, after: "3") { customerId } }`, nil, "")
+	require.Empty(t, res.Errors)
+	assert.JSONEq(t, `{"customers":[{"customerId":"4"},{"customerId":"5"}]}`, marshal(t, res.Data))
+
+	res = schema.Execute(context.Background(), `{ customers { customerId } }`, nil, "")
+	require.Empty(t, res.Errors)
+	page, _ := res.Data.Get("customers")
+	assert.Len(t, page, defaultPageSize)
+
+	res = schema.Execute(context.Background(), `{ customers(first: 101) { customerId } }`, nil, "")
+	require.Len(t, res.Errors, 1)
+	assert.Equal(t, `argument "first" must be between 1 and 100`, res.Errors[0].Message)
+}
+
+func TestBillingSchemaLimits(t *testing.T) {
+	res := billingFixture().Execute(context.Background(), `{
+		customer(id: "1") { loan { customer { loan { customer { loan { id } } } } } }
+	}`, nil, "")
+	assert.Nil(t, res.Data)
+	require.Len(t, res.Errors, 1)
+	assert.Equal(t, "query depth 7 exceeds the limit of 6", res.Errors[0].Message)
+
+	res = billingFixture().Execute(context.Background(), `{
+		customers(first: 100) { loan { schedule { weekNumber dueDate dueAmount status } } }
+	}`, nil, "")
+	assert.Nil(t, res.Data)
+	require.Len(t, res.Errors, 1)
+	assert.Equal(t, "query would resolve more than 10000 fields", res.Errors[0].Message)
+}
@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"billing-engine/internal/api/handler/dto"
+	"billing-engine/internal/domain/agreement"
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/pkg/apperrors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// AgreementHandler serves loans created in workflow mode: their agreement,
+// the provider's callbacks about it and their disbursement.
+type AgreementHandler struct {
+	service agreement.Service
+	loans   loan.LoanService
+	logger  *slog.Logger
+}
+
+func NewAgreementHandler(s agreement.Service, loans loan.LoanService, l *slog.Logger) *AgreementHandler {
+	if s == nil {
+		panic("agreement service cannot be nil")
+	}
+	if loans == nil {
+		panic("loan service cannot be nil")
+	}
+	if l == nil {
+		panic("logger cannot be nil")
+	}
+	return &AgreementHandler{service: s, loans: loans, logger: l.With("component", "AgreementHandler")}
+}
+
+func (h *AgreementHandler) loanIDFromURL(r *http.Request) (int64, error) {
+	return resolveURLID(r.Context(), "loanID", chi.URLParam(r, "loanID"), h.loans.ResolveLoanID)
+}
+
+// CreateWorkflowLoan handles POST /loans/workflow
+// @Summary Create a loan in workflow mode
+// @Description Creates a loan as POST /loans does, places it on hold and sends its agreement to the customer's primary verified email for e-signature. Payments are refused while the loan is on hold; it is released by POST /loans/{loanID}/disbursement once the agreement is signed. When the loan was created but its agreement could not be sent, the answer is an error and POST /loans/{loanID}/agreement sends it again.
+// @Tags Agreements
+// @Accept json
+// @Produce json
+// @Param request body dto.CreateLoanRequest true "Loan creation request payload"
+// @Success 201 {object} dto.WorkflowLoanResponse "Loan created and its agreement sent"
+// @Failure 400 {object} dto.ErrorResponse "Invalid request payload or unknown product, or the customer has no verified email to sign from"
+// @Failure 409 {object} dto.ErrorResponse "Customer already has a loan that is not paid off, or the external reference or public ID is taken"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Failure 503 {object} dto.ErrorResponse "No e-signature provider is configured, or it could not be reached"
+// @Router /loans/workflow [post]
+// @Security BearerAuth
+func (h *AgreementHandler) CreateWorkflowLoan(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreateLoanRequest
+	if err := decodeJSON(r, &req); err != nil {
+		respondError(w, fmt.Errorf("%w: %w", apperrors.ErrInvalidArgument, err))
+		return
+	}
+	if err := req.Validate(); err != nil {
+		respondError(w, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err))
+		return
+	}
+
+	startDate, _ := time.Parse(time.RFC3339[:10], req.StartDate)
+	terms := agreement.NewLoan{
+		CustomerID: req.CustomerID, Principal: req.Principal, TermWeeks: req.TermWeeks, AnnualInterestRate: req.AnnualInterestRate,
+		StartDate: startDate, ExternalRef: req.ExternalRef, PublicID: req.PublicUUID(), Product: req.Product,
+	}
+	l, a, err := h.service.CreateLoan(r.Context(), terms, actorFromContext(r.Context()))
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "Service failed to create workflow loan", slog.Int64("customerID", req.CustomerID), slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, dto.NewWorkflowLoanResponse(l, a))
+}
+
+// GetAgreement handles GET /loans/{loanID}/agreement
+// @Summary Get a loan's agreement
+// @Description Returns the most recent agreement of a loan created in workflow mode.
+// @Tags Agreements
+// @Produce json
+// @Param loanID path string true "Loan ID or public UUID"
+// @Success 200 {object} dto.AgreementResponse
+// @Failure 404 {object} dto.ErrorResponse "Loan not found, or not created in workflow mode"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /loans/{loanID}/agreement [get]
+// @Security BearerAuth
+func (h *AgreementHandler) GetAgreement(w http.ResponseWriter, r *http.Request) {
+	loanID, err := h.loanIDFromURL(r)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	a, err := h.service.Get(r.Context(), loanID)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, dto.NewAgreementResponse(a))
+}
+
+// SendAgreement handles POST /loans/{loanID}/agreement
+// @Summary Send a loan's agreement
+// @Description Sends the loan a new agreement for signature, such as after the last one was declined or voided, placing it back on hold. A loan whose agreement is still awaiting signature or signed gets that one back.
+// @Tags Agreements
+// @Produce json
+// @Param loanID path string true "Loan ID or public UUID"
+// @Success 200 {object} dto.AgreementResponse "The agreement sent, or the one still standing"
+// @Failure 400 {object} dto.ErrorResponse "The customer has no verified email to sign from"
+// @Failure 404 {object} dto.ErrorResponse "Loan not found"
+// @Failure 409 {object} dto.ErrorResponse "Loan has no customer"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Failure 503 {object} dto.ErrorResponse "No e-signature provider is configured, or it could not be reached"
+// @Router /loans/{loanID}/agreement [post]
+// @Security BearerAuth
+func (h *AgreementHandler) SendAgreement(w http.ResponseWriter, r *http.Request) {
+	loanID, err := h.loanIDFromURL(r)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	a, err := h.service.Send(r.Context(), loanID, actorFromContext(r.Context()))
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "Service failed to send agreement", slog.Int64("loanID", loanID), slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, dto.NewAgreementResponse(a))
+}
+
+// Disburse handles POST /loans/{loanID}/disbursement
+// @Summary Disburse a workflow loan
+// @Description Records that a loan created in workflow mode was paid out and releases the hold it awaited signature under. Refused until its agreement is COMPLETED; disbursing a loan again returns its agreement unchanged.
+// @Tags Agreements
+// @Produce json
+// @Param loanID path string true "Loan ID or public UUID"
+// @Success 200 {object} dto.AgreementResponse "The signed agreement, with the disbursement"
+// @Failure 404 {object} dto.ErrorResponse "Loan not found"
+// @Failure 409 {object} dto.ErrorResponse "Agreement not signed, or loan not created in workflow mode"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /loans/{loanID}/disbursement [post]
+// @Security BearerAuth
+func (h *AgreementHandler) Disburse(w http.ResponseWriter, r *http.Request) {
+	loanID, err := h.loanIDFromURL(r)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	a, err := h.service.Disburse(r.Context(), loanID, actorFromContext(r.Context()))
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "Service failed to disburse loan", slog.Int64("loanID", loanID), slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, dto.NewAgreementResponse(a))
+}
+
+// HandleCallback handles POST /esign/callback
+// @Summary E-signature provider callback
+// @Description Called by the e-signature provider as an envelope is signed, declined or voided. It carries no bearer token: the provider signs the body instead, in `X-Signature` as `sha256=` and the hex HMAC-SHA256 of the body keyed with the callback secret. A repeated callback changes nothing.
+// @Tags Agreements
+// @Accept json
+// @Produce json
+// @Param X-Signature header string true "sha256= and the hex HMAC-SHA256 of the body"
+// @Success 200 {object} dto.AgreementResponse "The envelope's agreement"
+// @Failure 400 {object} dto.ErrorResponse "Malformed callback or unknown status"
+// @Failure 401 {object} dto.ErrorResponse "Signature missing or wrong"
+// @Failure 404 {object} dto.ErrorResponse "Unknown envelope"
+// @Failure 409 {object} dto.ErrorResponse "Envelope already signed, declined or voided"
+// @Failure 503 {object} dto.ErrorResponse "No e-signature provider is configured"
+// @Router /esign/callback [post]
+func (h *AgreementHandler) HandleCallback(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		respondError(w, fmt.Errorf("%w: read request body: %w", apperrors.ErrInvalidArgument, err))
+		return
+	}
+
+	a, err := h.service.HandleCallback(r.Context(), body, r.Header)
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "Refused e-signature callback", slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, dto.NewAgreementResponse(a))
+}
@@ -0,0 +1,195 @@
+package handler_test
+
+import (
+	"billing-engine/internal/api/handler"
+	"billing-engine/internal/api/handler/dto"
+	"billing-engine/internal/domain/agreement"
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockAgreementService struct {
+	mock.Mock
+}
+
+func (m *MockAgreementService) CreateLoan(ctx context.Context, terms agreement.NewLoan, createdBy string) (*loan.Loan, *agreement.Agreement, error) {
+	args := m.Called(ctx, terms, createdBy)
+	l, _ := args.Get(0).(*loan.Loan)
+	a, _ := args.Get(1).(*agreement.Agreement)
+	return l, a, args.Error(2)
+}
+
+func (m *MockAgreementService) Send(ctx context.Context, loanID int64, sentBy string) (*agreement.Agreement, error) {
+	args := m.Called(ctx, loanID, sentBy)
+	a, _ := args.Get(0).(*agreement.Agreement)
+	return a, args.Error(1)
+}
+
+func (m *MockAgreementService) Get(ctx context.Context, loanID int64) (*agreement.Agreement, error) {
+	args := m.Called(ctx, loanID)
+	a, _ := args.Get(0).(*agreement.Agreement)
+	return a, args.Error(1)
+}
+
+func (m *MockAgreementService) HandleCallback(ctx context.Context, body []byte, header http.Header) (*agreement.Agreement, error) {
+	args := m.Called(ctx, body, header)
+	a, _ := args.Get(0).(*agreement.Agreement)
+	return a, args.Error(1)
+}
+
+func (m *MockAgreementService) Disburse(ctx context.Context, loanID int64, disbursedBy string) (*agreement.Agreement, error) {
+	args := m.Called(ctx, loanID, disbursedBy)
+	a, _ := args.Get(0).(*agreement.Agreement)
+	return a, args.Error(1)
+}
+
+func newAgreementHandler(svc agreement.Service) *handler.AgreementHandler {
+	return handler.NewAgreementHandler(svc, stubNoteLoanService{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func testAgreement(status agreement.Status) *agreement.Agreement {
+	documentID := int64(9)
+	return &agreement.Agreement{ID: 3, LoanID: 42, DocumentID: &documentID, Provider: "http", EnvelopeID: "env-1", Status: status,
+		SentBy: "ops", SentAt: time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC), UpdatedAt: time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC)}
+}
+
+func TestAgreementHandlerCreateWorkflowLoan(t *testing.T) {
+	body := `{"customerId":7,"principal":5000000,"termWeeks":50,"annualInterestRate":0.1,"startDate":"2025-03-03"}`
+
+	t.Run("creates the loan and sends its agreement", func(t *testing.T) {
+		svc := new(MockAgreementService)
+		terms := agreement.NewLoan{CustomerID: 7, Principal: 5000000, TermWeeks: 50, AnnualInterestRate: 0.1, StartDate: time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)}
+		svc.On("CreateLoan", mock.Anything, terms, "").
+			Return(&loan.Loan{ID: 42, PrincipalAmount: 5000000, TermWeeks: 50}, testAgreement(agreement.StatusSent), nil).Once()
+
+		rec := httptest.NewRecorder()
+		newAgreementHandler(svc).CreateWorkflowLoan(rec, httptest.NewRequest(http.MethodPost, "/loans/workflow", strings.NewReader(body)))
+
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		var resp dto.WorkflowLoanResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, "SENT", resp.Agreement.Status)
+		assert.Equal(t, "9", resp.Agreement.DocumentID)
+		assert.Equal(t, "env-1", resp.Agreement.EnvelopeID)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("rejects an invalid request", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		newAgreementHandler(new(MockAgreementService)).CreateWorkflowLoan(rec,
+			httptest.NewRequest(http.MethodPost, "/loans/workflow", strings.NewReader(`{"customerId":7,"principal":0}`)))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("reports a missing provider", func(t *testing.T) {
+		svc := new(MockAgreementService)
+		svc.On("CreateLoan", mock.Anything, mock.Anything, "").
+			Return(nil, nil, fmt.Errorf("%w: no e-signature provider is configured", apperrors.ErrUnavailable)).Once()
+
+		rec := httptest.NewRecorder()
+		newAgreementHandler(svc).CreateWorkflowLoan(rec, httptest.NewRequest(http.MethodPost, "/loans/workflow", strings.NewReader(body)))
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	})
+}
+
+func TestAgreementHandlerGetAgreement(t *testing.T) {
+	svc := new(MockAgreementService)
+	svc.On("Get", mock.Anything, int64(42)).Return(testAgreement(agreement.StatusCompleted), nil).Once()
+	svc.On("Get", mock.Anything, int64(43)).Return(nil, fmt.Errorf("%w: agreement of loan 43", apperrors.ErrNotFound)).Once()
+
+	rec := httptest.NewRecorder()
+	newAgreementHandler(svc).GetAgreement(rec, withURLParams(httptest.NewRequest(http.MethodGet, "/loans/42/agreement", nil), map[string]string{"loanID": "42"}))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var resp dto.AgreementResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "COMPLETED", resp.Status)
+	assert.Equal(t, "42", resp.LoanID)
+
+	rec = httptest.NewRecorder()
+	newAgreementHandler(svc).GetAgreement(rec, withURLParams(httptest.NewRequest(http.MethodGet, "/loans/43/agreement", nil), map[string]string{"loanID": "43"}))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestAgreementHandlerSendAgreement(t *testing.T) {
+	svc := new(MockAgreementService)
+	svc.On("Send", mock.Anything, int64(42), "").Return(testAgreement(agreement.StatusSent), nil).Once()
+
+	rec := httptest.NewRecorder()
+	newAgreementHandler(svc).SendAgreement(rec, withURLParams(httptest.NewRequest(http.MethodPost, "/loans/42/agreement", nil), map[string]string{"loanID": "42"}))
+
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	svc.AssertExpectations(t)
+}
+
+func TestAgreementHandlerDisburse(t *testing.T) {
+	t.Run("disburses a signed loan", func(t *testing.T) {
+		svc := new(MockAgreementService)
+		disbursed := testAgreement(agreement.StatusCompleted)
+		now := time.Now()
+		disbursed.DisbursedBy, disbursed.DisbursedAt = "ops", &now
+		svc.On("Disburse", mock.Anything, int64(42), "").Return(disbursed, nil).Once()
+
+		rec := httptest.NewRecorder()
+		newAgreementHandler(svc).Disburse(rec, withURLParams(httptest.NewRequest(http.MethodPost, "/loans/42/disbursement", nil), map[string]string{"loanID": "42"}))
+
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var resp dto.AgreementResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.NotNil(t, resp.DisbursedAt)
+	})
+
+	t.Run("refuses an unsigned loan", func(t *testing.T) {
+		svc := new(MockAgreementService)
+		svc.On("Disburse", mock.Anything, int64(42), "").Return(nil, fmt.Errorf("%w: the agreement of loan 42 is SENT", apperrors.ErrConflict)).Once()
+
+		rec := httptest.NewRecorder()
+		newAgreementHandler(svc).Disburse(rec, withURLParams(httptest.NewRequest(http.MethodPost, "/loans/42/disbursement", nil), map[string]string{"loanID": "42"}))
+
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+}
+
+func TestAgreementHandlerHandleCallback(t *testing.T) {
+	body := `{"envelopeId":"env-1","status":"COMPLETED"}`
+
+	t.Run("passes the body and signature on", func(t *testing.T) {
+		svc := new(MockAgreementService)
+		svc.On("HandleCallback", mock.Anything, []byte(body), mock.MatchedBy(func(h http.Header) bool { return h.Get("X-Signature") == "sha256=abc" })).
+			Return(testAgreement(agreement.StatusCompleted), nil).Once()
+
+		req := httptest.NewRequest(http.MethodPost, "/esign/callback", strings.NewReader(body))
+		req.Header.Set("X-Signature", "sha256=abc")
+		rec := httptest.NewRecorder()
+		newAgreementHandler(svc).HandleCallback(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("refuses a callback that is not signed", func(t *testing.T) {
+		svc := new(MockAgreementService)
+		svc.On("HandleCallback", mock.Anything, []byte(body), mock.Anything).
+			Return(nil, fmt.Errorf("%w: callback signature does not match", apperrors.ErrUnauthorized)).Once()
+
+		rec := httptest.NewRecorder()
+		newAgreementHandler(svc).HandleCallback(rec, httptest.NewRequest(http.MethodPost, "/esign/callback", strings.NewReader(body)))
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+}
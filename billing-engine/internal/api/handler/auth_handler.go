@@ -2,39 +2,45 @@ package handler
 
 import (
 	"billing-engine/internal/api/handler/dto"
+	mw "billing-engine/internal/api/middleware"
 	"billing-engine/internal/config"
 	"billing-engine/internal/pkg/apperrors"
 	"encoding/json"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
 type AuthHandler struct {
-	cfg    config.Config
-	logger *slog.Logger
+	cfg         config.Config
+	adminCaller func(*http.Request) bool
+	logger      *slog.Logger
 }
 
 func NewAuthHandler(cfg config.Config, l *slog.Logger) *AuthHandler {
+	logger := l.With("component", "AuthHandler")
 	return &AuthHandler{
-		cfg:    cfg,
-		logger: l.With("component", "AuthHandler"),
+		cfg:         cfg,
+		adminCaller: mw.AdminCaller(cfg.Server.Auth, logger),
+		logger:      logger,
 	}
 }
 
 // GenerateBearerToken generates a JWT bearer token using the provided secret.
 //
 // @Summary Generate a JWT bearer token
-// @Description This function generates a JWT bearer token based on a given secret.
+// @Description This function generates a JWT bearer token based on a given secret. When customerId is provided the token is issued with the read-only customer scope used by the /me routes; admin issues the admin scope used by the /admin routes, and only to a caller that already sends a valid admin token.
 // @Tags Authentication
 // @Accept json
 // @Produce json
 // @Param request body dto.TokenRequest true "username"
 // @Success 200 {object} map[string]string "Token successfully generated"
 // @Failure 400 {object} dto.ErrorResponse "Invalid request parameters"
+// @Failure 403 {object} dto.ErrorResponse "Admin scope requested without an admin token"
 // @Failure 500 {object} dto.ErrorResponse "Internal server error"
 // @Router /auth/token [post]
 func (h *AuthHandler) GenerateBearerToken(w http.ResponseWriter, r *http.Request) {
@@ -55,6 +61,38 @@ func (h *AuthHandler) GenerateBearerToken(w http.ResponseWriter, r *http.Request
 		"username": req.Username,
 		"exp":      time.Now().Add(24 * time.Hour).Unix(),
 	}
+	// Issue tokens the auth middleware accepts when it enforces iss and aud.
+	if iss := h.cfg.Server.Auth.Issuer; iss != "" {
+		claims["iss"] = iss
+	}
+	if aud := h.cfg.Server.Auth.Audience; aud != "" {
+		claims["aud"] = aud
+	}
+	if req.CustomerID < 0 {
+		h.logger.Error("customerId must be positive")
+		respondError(w, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, "customerId must be positive"))
+		return
+	}
+	if req.CustomerID > 0 && req.Admin {
+		h.logger.Error("customerId and admin are mutually exclusive")
+		respondError(w, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, "a customer token cannot have the admin scope"))
+		return
+	}
+	// The endpoint is open, so the admin scope is only passed on by someone
+	// who holds it: the first admin token comes from the identity provider
+	// or is signed by the operator with the secret.
+	if req.Admin && !h.adminCaller(r) {
+		h.logger.Warn("admin scope requested without an admin token", "username", req.Username)
+		respondError(w, fmt.Errorf("%w: %v", apperrors.ErrForbidden, "the admin scope needs an admin token"))
+		return
+	}
+	if req.CustomerID > 0 {
+		claims["sub"] = strconv.FormatInt(req.CustomerID, 10)
+		claims["scope"] = mw.ScopeCustomer
+	}
+	if req.Admin {
+		claims["scope"] = mw.ScopeAdmin
+	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
 	tokenString, _ := token.SignedString([]byte(h.cfg.Server.Auth.JWTSecret))
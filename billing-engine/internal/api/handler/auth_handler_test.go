@@ -9,10 +9,12 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"log/slog"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -98,3 +100,123 @@ func TestGenerateBearerToken(t *testing.T) {
 		assert.Contains(t, respBody.Error.Message, "username is required")
 	})
 }
+
+func TestGenerateCustomerScopedToken(t *testing.T) {
+	mockCfg := newTestConfig()
+	handler := NewAuthHandler(mockCfg, logger)
+
+	reqBody := dto.TokenRequest{Username: "borrower", CustomerID: 42}
+	body, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest(http.MethodPost, "/auth/token", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.GenerateBearerToken(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var respBody map[string]string
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&respBody))
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(strings.TrimPrefix(respBody["token"], "Bearer "), claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(mockCfg.Server.Auth.JWTSecret), nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "42", claims["sub"])
+	assert.Equal(t, "customer", claims["scope"])
+}
+
+func TestGenerateAdminScopedToken(t *testing.T) {
+	mockCfg := newTestConfig()
+	handler := NewAuthHandler(mockCfg, logger)
+
+	signed := func(claims jwt.MapClaims) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(mockCfg.Server.Auth.JWTSecret))
+		assert.NoError(t, err)
+		return "Bearer " + token
+	}
+
+	t.Run("admin scope", func(t *testing.T) {
+		body, _ := json.Marshal(dto.TokenRequest{Username: "ops", Admin: true})
+		req := httptest.NewRequest(http.MethodPost, "/auth/token", bytes.NewReader(body))
+		req.Header.Set("Authorization", signed(jwt.MapClaims{"username": "lead", "scope": "admin"}))
+		w := httptest.NewRecorder()
+
+		handler.GenerateBearerToken(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var respBody map[string]string
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&respBody))
+
+		claims := jwt.MapClaims{}
+		_, err := jwt.ParseWithClaims(strings.TrimPrefix(respBody["token"], "Bearer "), claims, func(token *jwt.Token) (interface{}, error) {
+			return []byte(mockCfg.Server.Auth.JWTSecret), nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "admin", claims["scope"])
+		assert.NotContains(t, claims, "sub")
+	})
+
+	t.Run("anonymous caller cannot be admin", func(t *testing.T) {
+		body, _ := json.Marshal(dto.TokenRequest{Username: "x", Admin: true})
+		req := httptest.NewRequest(http.MethodPost, "/auth/token", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+
+		handler.GenerateBearerToken(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.NotContains(t, w.Body.String(), "token\"")
+	})
+
+	t.Run("staff token cannot be made admin", func(t *testing.T) {
+		body, _ := json.Marshal(dto.TokenRequest{Username: "ops", Admin: true})
+		req := httptest.NewRequest(http.MethodPost, "/auth/token", bytes.NewReader(body))
+		req.Header.Set("Authorization", signed(jwt.MapClaims{"username": "ops"}))
+		w := httptest.NewRecorder()
+
+		handler.GenerateBearerToken(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("admin token signed with another secret", func(t *testing.T) {
+		forged, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"scope": "admin"}).SignedString([]byte("guessed"))
+		body, _ := json.Marshal(dto.TokenRequest{Username: "ops", Admin: true})
+		req := httptest.NewRequest(http.MethodPost, "/auth/token", bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+forged)
+		w := httptest.NewRecorder()
+
+		handler.GenerateBearerToken(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("customer cannot be admin", func(t *testing.T) {
+		body, _ := json.Marshal(dto.TokenRequest{Username: "borrower", CustomerID: 42, Admin: true})
+		req := httptest.NewRequest(http.MethodPost, "/auth/token", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+
+		handler.GenerateBearerToken(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestGenerateTokenWithIssuerAndAudience(t *testing.T) {
+	mockCfg := newTestConfig()
+	mockCfg.Server.Auth.Issuer = "https://id.example.com"
+	mockCfg.Server.Auth.Audience = "billing-engine"
+	handler := NewAuthHandler(mockCfg, logger)
+
+	body, _ := json.Marshal(dto.TokenRequest{Username: "ops"})
+	w := httptest.NewRecorder()
+	handler.GenerateBearerToken(w, httptest.NewRequest(http.MethodPost, "/auth/token", bytes.NewReader(body)))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var respBody map[string]string
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&respBody))
+
+	_, err := jwt.Parse(strings.TrimPrefix(respBody["token"], "Bearer "), func(token *jwt.Token) (interface{}, error) {
+		return []byte(mockCfg.Server.Auth.JWTSecret), nil
+	}, jwt.WithIssuer("https://id.example.com"), jwt.WithAudience("billing-engine"), jwt.WithExpirationRequired())
+	assert.NoError(t, err)
+}
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"billing-engine/internal/api/handler/dto"
+	mw "billing-engine/internal/api/middleware"
+	"billing-engine/internal/jobs"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// bulkRetryAfter is the Retry-After, in seconds, sent with a 503 while the
+// job queue is full.
+const bulkRetryAfter = "30"
+
+// BulkRunner decides whether an upload is processed within the request or
+// queued as a background job, so that large uploads do not hold a request
+// goroutine for minutes.
+type BulkRunner struct {
+	jobs        *jobs.Runner
+	syncMaxRows int
+	logger      *slog.Logger
+}
+
+// NewBulkRunner queues uploads of more than syncMaxRows rows, and those whose
+// client sends Prefer: respond-async, on runner. A nil runner processes every
+// upload within the request; zero or less syncMaxRows only queues uploads
+// that ask for it.
+func NewBulkRunner(runner *jobs.Runner, syncMaxRows int, l *slog.Logger) *BulkRunner {
+	if l == nil {
+		panic("logger cannot be nil")
+	}
+	return &BulkRunner{jobs: runner, syncMaxRows: syncMaxRows, logger: l.With("component", "BulkRunner")}
+}
+
+// bulkTask does the work of an upload of type T. Small uploads run it within
+// the request; large ones run it as a job on whichever instance claims it,
+// so it must depend on nothing but its input.
+type bulkTask[T any] func(ctx context.Context, input T, progress func(processed int)) (any, error)
+
+// registerBulkTask lets the job runner run task for jobs of kind, decoding
+// the input each job was queued with. It does nothing when b has no runner.
+func registerBulkTask[T any](b *BulkRunner, kind string, task bulkTask[T]) {
+	if b == nil || b.jobs == nil {
+		return
+	}
+	b.jobs.Register(kind, func(ctx context.Context, job jobs.Job, progress func(int)) (any, error) {
+		var input T
+		if err := job.DecodePayload(&input); err != nil {
+			return nil, err
+		}
+		return task(ctx, input, progress)
+	})
+}
+
+// respondBulk runs task on input and answers 200 with its result, or queues
+// input as a job of kind and answers 202 Accepted with the job and its URL in
+// Location, below the API version the request came in on. rows is the size of the upload and the job's total. A nil
+// BulkRunner always runs task within the request. The kind must have been
+// registered with registerBulkTask.
+func respondBulk[T any](b *BulkRunner, w http.ResponseWriter, r *http.Request, kind string, rows int, input T, task bulkTask[T]) {
+	asked := prefersAsync(r)
+	if b == nil || b.jobs == nil || (!asked && (b.syncMaxRows <= 0 || rows <= b.syncMaxRows)) {
+		result, err := task(r.Context(), input, func(int) {})
+		if err != nil {
+			respondError(w, err)
+			return
+		}
+		respondJSON(w, http.StatusOK, result)
+		return
+	}
+
+	job, err := b.jobs.Submit(r.Context(), jobs.Spec{Kind: kind, Total: rows, Payload: input})
+	if err != nil {
+		if errors.Is(err, jobs.ErrQueueFull) {
+			w.Header().Set("Retry-After", bulkRetryAfter)
+		}
+		respondError(w, err)
+		return
+	}
+	b.logger.InfoContext(r.Context(), "Bulk request handed to a background job",
+		slog.String("job_id", job.ID), slog.String("kind", kind), slog.Int("rows", rows))
+	if asked {
+		w.Header().Set("Preference-Applied", "respond-async")
+	}
+	w.Header().Set("Location", mw.VersionPrefix(r.Context())+"/jobs/"+job.ID)
+	respondJSON(w, http.StatusAccepted, dto.NewJobResponse(job))
+}
+
+// prefersAsync reports whether the Prefer header holds respond-async
+// (RFC 7240).
+func prefersAsync(r *http.Request) bool {
+	for _, header := range r.Header.Values("Prefer") {
+		for _, preference := range strings.Split(header, ",") {
+			token, _, _ := strings.Cut(preference, ";")
+			if strings.EqualFold(strings.TrimSpace(token), "respond-async") {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,300 @@
+package handler
+
+import (
+	"billing-engine/internal/api/handler/dto"
+	"billing-engine/internal/domain/collections"
+	"billing-engine/internal/pkg/apperrors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+type CollectionsHandler struct {
+	service collections.Service
+	logger  *slog.Logger
+}
+
+func NewCollectionsHandler(s collections.Service, l *slog.Logger) *CollectionsHandler {
+	if s == nil {
+		panic("collections service cannot be nil")
+	}
+	if l == nil {
+		panic("logger cannot be nil")
+	}
+	return &CollectionsHandler{
+		service: s,
+		logger:  l.With("component", "CollectionsHandler"),
+	}
+}
+
+// GetQueue handles GET /collections/queue
+// @Summary Get a collector's queue
+// @Description Lists the open assignments of a collector, most days past due first. Without the collector parameter the queue of the authenticated staff member is returned.
+// @Tags Collections
+// @Produce json
+// @Param collector query string false "Collector; defaults to the caller"
+// @Success 200 {array} dto.CollectionAssignmentResponse "Open assignments"
+// @Failure 400 {object} dto.ErrorResponse "No collector given"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /collections/queue [get]
+// @Security BearerAuth
+func (h *CollectionsHandler) GetQueue(w http.ResponseWriter, r *http.Request) {
+	collector := strings.TrimSpace(r.URL.Query().Get("collector"))
+	if collector == "" {
+		collector = actorFromContext(r.Context())
+	}
+	if collector == "" {
+		respondError(w, fmt.Errorf("%w: collector query parameter is required", apperrors.ErrInvalidArgument))
+		return
+	}
+
+	queue, err := h.service.ListQueue(r.Context(), collector)
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "Service failed to list collection queue", slog.String("collector", collector), slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, dto.NewCollectionQueueResponse(queue))
+}
+
+// CreateRule handles POST /collections/rules
+// @Summary Create a collection rule
+// @Description Adds an assignment rule. The nightly run gives each unassigned past-due loan to the collector of the first matching rule by priority.
+// @Tags Collections
+// @Accept json
+// @Produce json
+// @Param request body dto.CreateCollectionRuleRequest true "Rule"
+// @Success 201 {object} dto.CollectionRuleResponse "Rule created"
+// @Failure 400 {object} dto.ErrorResponse "Invalid request payload"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /collections/rules [post]
+// @Security BearerAuth
+func (h *CollectionsHandler) CreateRule(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreateCollectionRuleRequest
+	if err := decodeJSON(r, &req); err != nil {
+		h.logger.WarnContext(r.Context(), "Failed to decode request body", slog.Any("error", err))
+		respondError(w, fmt.Errorf("%w: %w", apperrors.ErrInvalidArgument, err))
+		return
+	}
+	if err := req.Validate(); err != nil {
+		respondError(w, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err))
+		return
+	}
+
+	created, err := h.service.CreateRule(r.Context(), req.Rule())
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "Service failed to create collection rule", slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, dto.NewCollectionRuleResponse(created))
+}
+
+// ListRules handles GET /collections/rules
+// @Summary List collection rules
+// @Description Lists the assignment rules in the order they are evaluated.
+// @Tags Collections
+// @Produce json
+// @Success 200 {array} dto.CollectionRuleResponse "Rules"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /collections/rules [get]
+// @Security BearerAuth
+func (h *CollectionsHandler) ListRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.service.ListRules(r.Context())
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "Service failed to list collection rules", slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, dto.NewCollectionRuleListResponse(rules))
+}
+
+// DeleteRule handles DELETE /collections/rules/{ruleID}
+// @Summary Delete a collection rule
+// @Description Stops the rule from matching. Loans it already assigned keep their collector.
+// @Tags Collections
+// @Param ruleID path int true "Rule ID"
+// @Success 204 "Rule deleted"
+// @Failure 404 {object} dto.ErrorResponse "Rule not found"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /collections/rules/{ruleID} [delete]
+// @Security BearerAuth
+func (h *CollectionsHandler) DeleteRule(w http.ResponseWriter, r *http.Request) {
+	ruleID, err := int64URLParam(r, "ruleID")
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	if err := h.service.DeleteRule(r.Context(), ruleID); err != nil {
+		h.logger.WarnContext(r.Context(), "Service failed to delete collection rule", slog.Int64("ruleID", ruleID), slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RecordAction handles POST /collections/assignments/{assignmentID}/actions
+// @Summary Record a collection action
+// @Description Records a call, SMS, visit, promise to pay or note against an open assignment. The authenticated staff member is kept as the collector who acted.
+// @Tags Collections
+// @Accept json
+// @Produce json
+// @Param assignmentID path int true "Assignment ID"
+// @Param request body dto.RecordCollectionActionRequest true "Action"
+// @Success 201 {object} dto.CollectionActionResponse "Action recorded"
+// @Failure 400 {object} dto.ErrorResponse "Invalid request payload"
+// @Failure 404 {object} dto.ErrorResponse "Assignment not found"
+// @Failure 409 {object} dto.ErrorResponse "Assignment is already resolved"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /collections/assignments/{assignmentID}/actions [post]
+// @Security BearerAuth
+func (h *CollectionsHandler) RecordAction(w http.ResponseWriter, r *http.Request) {
+	assignmentID, err := int64URLParam(r, "assignmentID")
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	var req dto.RecordCollectionActionRequest
+	if err := decodeJSON(r, &req); err != nil {
+		h.logger.WarnContext(r.Context(), "Failed to decode request body", slog.Any("error", err))
+		respondError(w, fmt.Errorf("%w: %w", apperrors.ErrInvalidArgument, err))
+		return
+	}
+	if err := req.Validate(); err != nil {
+		respondError(w, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err))
+		return
+	}
+
+	action, err := h.service.RecordAction(r.Context(), assignmentID, actorFromContext(r.Context()), req.Action())
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "Service failed to record collection action", slog.Int64("assignmentID", assignmentID), slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, dto.NewCollectionActionResponse(action))
+}
+
+// ListActions handles GET /collections/assignments/{assignmentID}/actions
+// @Summary List collection actions
+// @Description Lists the actions and reassignments recorded against an assignment, newest first.
+// @Tags Collections
+// @Produce json
+// @Param assignmentID path int true "Assignment ID"
+// @Success 200 {array} dto.CollectionActionResponse "Actions"
+// @Failure 404 {object} dto.ErrorResponse "Assignment not found"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /collections/assignments/{assignmentID}/actions [get]
+// @Security BearerAuth
+func (h *CollectionsHandler) ListActions(w http.ResponseWriter, r *http.Request) {
+	assignmentID, err := int64URLParam(r, "assignmentID")
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	actions, err := h.service.ListActions(r.Context(), assignmentID)
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "Service failed to list collection actions", slog.Int64("assignmentID", assignmentID), slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, dto.NewCollectionActionListResponse(actions))
+}
+
+// Reassign handles PUT /collections/assignments/{assignmentID}/collector
+// @Summary Reassign a loan to another collector
+// @Description Moves an open assignment to another collector and records the reassignment, with who made it and why, in the action history.
+// @Tags Collections
+// @Accept json
+// @Produce json
+// @Param assignmentID path int true "Assignment ID"
+// @Param request body dto.ReassignCollectionRequest true "New collector"
+// @Success 200 {object} dto.CollectionAssignmentResponse "Assignment reassigned"
+// @Failure 400 {object} dto.ErrorResponse "Invalid request payload"
+// @Failure 404 {object} dto.ErrorResponse "Assignment not found"
+// @Failure 409 {object} dto.ErrorResponse "Assignment is already resolved"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /collections/assignments/{assignmentID}/collector [put]
+// @Security BearerAuth
+func (h *CollectionsHandler) Reassign(w http.ResponseWriter, r *http.Request) {
+	assignmentID, err := int64URLParam(r, "assignmentID")
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	var req dto.ReassignCollectionRequest
+	if err := decodeJSON(r, &req); err != nil {
+		h.logger.WarnContext(r.Context(), "Failed to decode request body", slog.Any("error", err))
+		respondError(w, fmt.Errorf("%w: %w", apperrors.ErrInvalidArgument, err))
+		return
+	}
+	if err := req.Validate(); err != nil {
+		respondError(w, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err))
+		return
+	}
+
+	assignment, err := h.service.Reassign(r.Context(), assignmentID, req.Collector, actorFromContext(r.Context()), req.Reason)
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "Service failed to reassign collection assignment", slog.Int64("assignmentID", assignmentID), slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, dto.NewCollectionAssignmentResponse(assignment))
+}
+
+// ListEscalationSteps handles GET /collections/escalation-steps
+// @Summary List the reminder ladder
+// @Description Lists the escalation steps by days past due. The daily escalation run sends each past-due loan the highest step it has reached, once.
+// @Tags Collections
+// @Produce json
+// @Success 200 {array} dto.EscalationStepResponse "Escalation steps"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /collections/escalation-steps [get]
+// @Security BearerAuth
+func (h *CollectionsHandler) ListEscalationSteps(w http.ResponseWriter, r *http.Request) {
+	steps, err := h.service.ListEscalationSteps(r.Context())
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "Service failed to list escalation steps", slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, dto.NewEscalationStepListResponse(steps))
+}
+
+// ReplaceEscalationSteps handles PUT /collections/escalation-steps
+// @Summary Replace the reminder ladder
+// @Description Replaces every escalation step. SMS and EMAIL steps remind the customer through notify-service; CALL and LETTER steps publish a task for the collections team. Requires the admin role.
+// @Tags Collections
+// @Accept json
+// @Produce json
+// @Param request body dto.ReplaceEscalationStepsRequest true "Ladder"
+// @Success 200 {array} dto.EscalationStepResponse "Ladder replaced"
+// @Failure 400 {object} dto.ErrorResponse "Invalid request payload"
+// @Failure 403 {object} dto.ErrorResponse "Caller is not an admin"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /collections/escalation-steps [put]
+// @Security BearerAuth
+func (h *CollectionsHandler) ReplaceEscalationSteps(w http.ResponseWriter, r *http.Request) {
+	var req dto.ReplaceEscalationStepsRequest
+	if err := decodeJSON(r, &req); err != nil {
+		h.logger.WarnContext(r.Context(), "Failed to decode request body", slog.Any("error", err))
+		respondError(w, fmt.Errorf("%w: %w", apperrors.ErrInvalidArgument, err))
+		return
+	}
+	if err := req.Validate(); err != nil {
+		respondError(w, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err))
+		return
+	}
+
+	steps, err := h.service.ReplaceEscalationSteps(r.Context(), req.Ladder())
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "Service failed to replace escalation steps", slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, dto.NewEscalationStepListResponse(steps))
+}
@@ -0,0 +1,253 @@
+package handler_test
+
+import (
+	"billing-engine/internal/api/handler"
+	"billing-engine/internal/api/handler/dto"
+	"billing-engine/internal/domain/collections"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockCollectionsService struct {
+	mock.Mock
+}
+
+func (m *MockCollectionsService) CreateRule(ctx context.Context, rule *collections.Rule) (*collections.Rule, error) {
+	args := m.Called(ctx, rule)
+	created, _ := args.Get(0).(*collections.Rule)
+	return created, args.Error(1)
+}
+
+func (m *MockCollectionsService) ListRules(ctx context.Context) ([]collections.Rule, error) {
+	args := m.Called(ctx)
+	rules, _ := args.Get(0).([]collections.Rule)
+	return rules, args.Error(1)
+}
+
+func (m *MockCollectionsService) DeleteRule(ctx context.Context, ruleID int64) error {
+	return m.Called(ctx, ruleID).Error(0)
+}
+
+func (m *MockCollectionsService) GetOpenAssignment(ctx context.Context, loanID int64) (*collections.Assignment, error) {
+	args := m.Called(ctx, loanID)
+	assignment, _ := args.Get(0).(*collections.Assignment)
+	return assignment, args.Error(1)
+}
+
+func (m *MockCollectionsService) ListQueue(ctx context.Context, collector string) ([]collections.Assignment, error) {
+	args := m.Called(ctx, collector)
+	queue, _ := args.Get(0).([]collections.Assignment)
+	return queue, args.Error(1)
+}
+
+func (m *MockCollectionsService) RecordAction(ctx context.Context, assignmentID int64, collector string, action *collections.Action) (*collections.Action, error) {
+	args := m.Called(ctx, assignmentID, collector, action)
+	recorded, _ := args.Get(0).(*collections.Action)
+	return recorded, args.Error(1)
+}
+
+func (m *MockCollectionsService) ListActions(ctx context.Context, assignmentID int64) ([]collections.Action, error) {
+	args := m.Called(ctx, assignmentID)
+	actions, _ := args.Get(0).([]collections.Action)
+	return actions, args.Error(1)
+}
+
+func (m *MockCollectionsService) Reassign(ctx context.Context, assignmentID int64, collector, by, reason string) (*collections.Assignment, error) {
+	args := m.Called(ctx, assignmentID, collector, by, reason)
+	assignment, _ := args.Get(0).(*collections.Assignment)
+	return assignment, args.Error(1)
+}
+
+func (m *MockCollectionsService) Assign(ctx context.Context) (*collections.RunReport, error) {
+	args := m.Called(ctx)
+	report, _ := args.Get(0).(*collections.RunReport)
+	return report, args.Error(1)
+}
+
+func (m *MockCollectionsService) ListEscalationSteps(ctx context.Context) ([]collections.EscalationStep, error) {
+	args := m.Called(ctx)
+	steps, _ := args.Get(0).([]collections.EscalationStep)
+	return steps, args.Error(1)
+}
+
+func (m *MockCollectionsService) ReplaceEscalationSteps(ctx context.Context, steps []collections.EscalationStep) ([]collections.EscalationStep, error) {
+	args := m.Called(ctx, steps)
+	ladder, _ := args.Get(0).([]collections.EscalationStep)
+	return ladder, args.Error(1)
+}
+
+func (m *MockCollectionsService) Escalate(ctx context.Context) (*collections.EscalationReport, error) {
+	args := m.Called(ctx)
+	report, _ := args.Get(0).(*collections.EscalationReport)
+	return report, args.Error(1)
+}
+
+func newCollectionsHandler(svc collections.Service) *handler.CollectionsHandler {
+	return handler.NewCollectionsHandler(svc, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestCollectionsHandlerGetQueue(t *testing.T) {
+	t.Run("lists the named collector's queue", func(t *testing.T) {
+		svc := new(MockCollectionsService)
+		svc.On("ListQueue", mock.Anything, "alice").Return([]collections.Assignment{
+			{ID: 9, LoanID: 3, Collector: "alice", Status: collections.AssignmentOpen, DaysPastDue: 95, Outstanding: 1200000},
+		}, nil).Once()
+
+		rr := httptest.NewRecorder()
+		newCollectionsHandler(svc).GetQueue(rr, httptest.NewRequest(http.MethodGet, "/collections/queue?collector=alice", nil))
+
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var resp []dto.CollectionAssignmentResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+		require.Len(t, resp, 1)
+		assert.Equal(t, "90+", resp[0].Bucket)
+		assert.Equal(t, "1200000.00", resp[0].Outstanding)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("needs a collector without authentication", func(t *testing.T) {
+		svc := new(MockCollectionsService)
+
+		rr := httptest.NewRecorder()
+		newCollectionsHandler(svc).GetQueue(rr, httptest.NewRequest(http.MethodGet, "/collections/queue", nil))
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		svc.AssertNotCalled(t, "ListQueue", mock.Anything, mock.Anything)
+	})
+}
+
+func TestCollectionsHandlerCreateRule(t *testing.T) {
+	t.Run("creates the rule", func(t *testing.T) {
+		svc := new(MockCollectionsService)
+		svc.On("CreateRule", mock.Anything, mock.MatchedBy(func(r *collections.Rule) bool {
+			return r.Collector == "alice" && r.Bucket == "90+" && r.MinOutstanding != nil && *r.MinOutstanding == 500000
+		})).Return(&collections.Rule{ID: 4, Name: "Late", Collector: "alice", Bucket: "90+", Active: true}, nil).Once()
+
+		body := `{"name":"Late","collector":"alice","bucket":"90+","minOutstanding":"500000","priority":1}`
+		rr := httptest.NewRecorder()
+		newCollectionsHandler(svc).CreateRule(rr, httptest.NewRequest(http.MethodPost, "/collections/rules", strings.NewReader(body)))
+
+		require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+		var resp dto.CollectionRuleResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+		assert.Equal(t, "4", resp.ID)
+		assert.True(t, resp.Active)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("rejects a bad amount", func(t *testing.T) {
+		body := `{"name":"Late","collector":"alice","maxOutstanding":"many"}`
+		rr := httptest.NewRecorder()
+		newCollectionsHandler(new(MockCollectionsService)).CreateRule(rr, httptest.NewRequest(http.MethodPost, "/collections/rules", strings.NewReader(body)))
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestCollectionsHandlerRecordAction(t *testing.T) {
+	t.Run("records a promise to pay", func(t *testing.T) {
+		svc := new(MockCollectionsService)
+		promised := time.Date(2025, 2, 10, 0, 0, 0, 0, time.UTC)
+		amount := 250000.0
+		svc.On("RecordAction", mock.Anything, int64(9), "", mock.MatchedBy(func(a *collections.Action) bool {
+			return a.Type == collections.ActionPromiseToPay && *a.PromisedAmount == 250000 && a.PromisedDate.Equal(promised)
+		})).Return(&collections.Action{ID: 1, AssignmentID: 9, LoanID: 3, Type: collections.ActionPromiseToPay,
+			PromisedAmount: &amount, PromisedDate: &promised}, nil).Once()
+
+		body := `{"type":"PROMISE_TO_PAY","promisedAmount":"250000","promisedDate":"2025-02-10"}`
+		req := withURLParams(httptest.NewRequest(http.MethodPost, "/collections/assignments/9/actions", strings.NewReader(body)),
+			map[string]string{"assignmentID": "9"})
+		rr := httptest.NewRecorder()
+		newCollectionsHandler(svc).RecordAction(rr, req)
+
+		require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+		var resp dto.CollectionActionResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+		require.NotNil(t, resp.PromisedDate)
+		assert.Equal(t, "2025-02-10", *resp.PromisedDate)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("resolved assignment", func(t *testing.T) {
+		svc := new(MockCollectionsService)
+		svc.On("RecordAction", mock.Anything, int64(9), "", mock.Anything).Return(nil, apperrors.ErrConflict).Once()
+
+		req := withURLParams(httptest.NewRequest(http.MethodPost, "/collections/assignments/9/actions", strings.NewReader(`{"type":"CALL"}`)),
+			map[string]string{"assignmentID": "9"})
+		rr := httptest.NewRecorder()
+		newCollectionsHandler(svc).RecordAction(rr, req)
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+	})
+}
+
+func TestCollectionsHandlerReassign(t *testing.T) {
+	svc := new(MockCollectionsService)
+	svc.On("Reassign", mock.Anything, int64(9), "bob", "", "alice is on leave").
+		Return(&collections.Assignment{ID: 9, LoanID: 3, Collector: "bob", Status: collections.AssignmentOpen}, nil).Once()
+
+	req := withURLParams(httptest.NewRequest(http.MethodPut, "/collections/assignments/9/collector",
+		strings.NewReader(`{"collector":"bob","reason":"alice is on leave"}`)), map[string]string{"assignmentID": "9"})
+	rr := httptest.NewRecorder()
+	newCollectionsHandler(svc).Reassign(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var resp dto.CollectionAssignmentResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	assert.Equal(t, "bob", resp.Collector)
+	svc.AssertExpectations(t)
+}
+
+func TestCollectionsHandlerReplaceEscalationSteps(t *testing.T) {
+	t.Run("replaces the ladder", func(t *testing.T) {
+		svc := new(MockCollectionsService)
+		svc.On("ReplaceEscalationSteps", mock.Anything, []collections.EscalationStep{
+			{DaysPastDue: 3, Action: "sms"}, {DaysPastDue: 21, Action: "CALL"},
+		}).Return([]collections.EscalationStep{
+			{DaysPastDue: 3, Action: collections.ReminderSMS}, {DaysPastDue: 21, Action: collections.ReminderCall},
+		}, nil).Once()
+
+		body := `{"steps":[{"daysPastDue":3,"action":"sms"},{"daysPastDue":21,"action":"CALL"}]}`
+		rr := httptest.NewRecorder()
+		newCollectionsHandler(svc).ReplaceEscalationSteps(rr, httptest.NewRequest(http.MethodPut, "/collections/escalation-steps", strings.NewReader(body)))
+
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var resp []dto.EscalationStepResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+		require.Len(t, resp, 2)
+		assert.True(t, resp[0].Notifies)
+		assert.False(t, resp[1].Notifies)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("requires the steps", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		newCollectionsHandler(new(MockCollectionsService)).ReplaceEscalationSteps(rr,
+			httptest.NewRequest(http.MethodPut, "/collections/escalation-steps", strings.NewReader(`{}`)))
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("invalid ladder", func(t *testing.T) {
+		svc := new(MockCollectionsService)
+		svc.On("ReplaceEscalationSteps", mock.Anything, mock.Anything).Return(nil, apperrors.ErrInvalidArgument).Once()
+
+		rr := httptest.NewRecorder()
+		newCollectionsHandler(svc).ReplaceEscalationSteps(rr, httptest.NewRequest(http.MethodPut, "/collections/escalation-steps",
+			strings.NewReader(`{"steps":[{"daysPastDue":0,"action":"SMS"}]}`)))
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
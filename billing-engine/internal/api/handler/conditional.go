@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// cacheControl lets the caller's own client keep a copy but makes it
+// revalidate on every use, which the ETag and Last-Modified checks turn into
+// a cheap 304. Responses depend on the caller's token, so shared caches must
+// not store them.
+const cacheControl = "private, no-cache"
+
+// respondCacheableJSON writes payload like respondJSON and tags it with an
+// ETag derived from the encoded body. A request whose If-None-Match already
+// names that ETag gets 304 without the body. The tag is weak because the
+// compression middleware may re-encode the body on the way out.
+func respondCacheableJSON(w http.ResponseWriter, r *http.Request, payload interface{}) {
+	response, err := json.Marshal(payload)
+	if err != nil {
+		slog.Default().Error("Failed to marshal JSON response", "error", err)
+		http.Error(w, `{"error":{"message":"Internal server error"}}`, http.StatusInternalServerError)
+		return
+	}
+
+	sum := sha256.Sum256(response)
+	etag := `W/"` + hex.EncodeToString(sum[:16]) + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", cacheControl)
+
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+}
+
+// etagMatches applies the weak comparison RFC 9110 prescribes for
+// If-None-Match to a comma-separated header value.
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	want := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == want {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified sets Last-Modified and reports whether the request's
+// If-Modified-Since already covers lastModified, in which case it has written
+// 304 and the handler should stop. If-None-Match takes precedence, as RFC 9110
+// requires, so requests carrying it are left to the ETag check.
+func notModified(w http.ResponseWriter, r *http.Request, lastModified time.Time) bool {
+	if lastModified.IsZero() {
+		return false
+	}
+	// The header only has second precision.
+	lastModified = lastModified.UTC().Truncate(time.Second)
+	w.Header().Set("Last-Modified", lastModified.Format(http.TimeFormat))
+	w.Header().Set("Cache-Control", cacheControl)
+
+	if r.Header.Get("If-None-Match") != "" {
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil || lastModified.After(since) {
+		return false
+	}
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
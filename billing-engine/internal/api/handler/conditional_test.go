@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestETagMatches(t *testing.T) {
+	etag := `W/"abc"`
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{"", false},
+		{`W/"abc"`, true},
+		{`"abc"`, true},
+		{`"xyz", W/"abc"`, true},
+		{`"xyz"`, false},
+		{"*", true},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, etagMatches(tt.header, etag), "If-None-Match: %s", tt.header)
+	}
+}
+
+func TestNotModifiedIgnoresIfModifiedSinceWithETag(t *testing.T) {
+	lastModified := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	req := httptest.NewRequest(http.MethodGet, "/loans/1", nil)
+	req.Header.Set("If-Modified-Since", lastModified.Format(http.TimeFormat))
+	req.Header.Set("If-None-Match", `W/"stale"`)
+	rec := httptest.NewRecorder()
+
+	assert.False(t, notModified(rec, req, lastModified))
+	assert.Equal(t, lastModified.Format(http.TimeFormat), rec.Header().Get("Last-Modified"))
+}
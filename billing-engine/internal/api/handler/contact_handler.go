@@ -0,0 +1,261 @@
+package handler
+
+import (
+	"billing-engine/internal/api/handler/dto"
+	"billing-engine/internal/domain/contact"
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/pkg/apperrors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type ContactHandler struct {
+	service   contact.Service
+	customers customer.CustomerService
+	logger    *slog.Logger
+}
+
+func NewContactHandler(s contact.Service, customers customer.CustomerService, l *slog.Logger) *ContactHandler {
+	if s == nil {
+		panic("contact service cannot be nil")
+	}
+	if customers == nil {
+		panic("customer service cannot be nil")
+	}
+	if l == nil {
+		panic("logger cannot be nil")
+	}
+	return &ContactHandler{service: s, customers: customers, logger: l.With("component", "ContactHandler")}
+}
+
+func (h *ContactHandler) customerFromURL(r *http.Request) (int64, error) {
+	return resolveURLID(r.Context(), "customerID", chi.URLParam(r, "customerID"), h.customers.ResolveCustomerID)
+}
+
+// contactFromURL resolves both IDs of a /contacts/{contactID} route.
+func (h *ContactHandler) contactFromURL(r *http.Request) (int64, int64, error) {
+	customerID, err := h.customerFromURL(r)
+	if err != nil {
+		return 0, 0, err
+	}
+	contactID, err := int64URLParam(r, "contactID")
+	if err != nil {
+		return 0, 0, err
+	}
+	return customerID, contactID, nil
+}
+
+// ListContacts handles GET /customers/{customerID}/contacts
+// @Summary List contacts
+// @Description Lists the customer's phone numbers and email addresses by type, the primary one first. Notifications only go to verified contacts.
+// @Tags Contacts
+// @Produce json
+// @Param customerID path string true "Customer ID or public UUID"
+// @Success 200 {array} dto.ContactResponse "Contacts"
+// @Failure 404 {object} dto.ErrorResponse "Customer not found"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /customers/{customerID}/contacts [get]
+// @Security BearerAuth
+func (h *ContactHandler) ListContacts(w http.ResponseWriter, r *http.Request) {
+	customerID, err := h.customerFromURL(r)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	contacts, err := h.service.ListContacts(r.Context(), customerID)
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "Service failed to list contacts", slog.Int64("customerID", customerID), slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, dto.NewContactListResponse(contacts))
+}
+
+// CreateContact handles POST /customers/{customerID}/contacts
+// @Summary Add a contact
+// @Description Adds a phone number, in international form, or an email address. The contact is unverified until the code sent to it is confirmed.
+// @Tags Contacts
+// @Accept json
+// @Produce json
+// @Param customerID path string true "Customer ID or public UUID"
+// @Param request body dto.CreateContactRequest true "Contact"
+// @Success 201 {object} dto.ContactResponse "Contact added"
+// @Failure 400 {object} dto.ErrorResponse "Invalid type or value"
+// @Failure 404 {object} dto.ErrorResponse "Customer not found"
+// @Failure 409 {object} dto.ErrorResponse "Customer already has this contact"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /customers/{customerID}/contacts [post]
+// @Security BearerAuth
+func (h *ContactHandler) CreateContact(w http.ResponseWriter, r *http.Request) {
+	customerID, err := h.customerFromURL(r)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	var req dto.CreateContactRequest
+	if err := decodeJSON(r, &req); err != nil {
+		h.logger.WarnContext(r.Context(), "Failed to decode request body", slog.Any("error", err))
+		respondError(w, fmt.Errorf("%w: %w", apperrors.ErrInvalidArgument, err))
+		return
+	}
+	if err := req.Validate(); err != nil {
+		respondError(w, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err))
+		return
+	}
+
+	created, err := h.service.AddContact(r.Context(), customerID, contact.Type(req.Type), req.Value)
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "Service failed to add contact", slog.Int64("customerID", customerID), slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, dto.NewContactResponse(created))
+}
+
+// UpdateContact handles PUT /customers/{customerID}/contacts/{contactID}
+// @Summary Update a contact
+// @Description Replaces the contact's value and primary flag. A changed value has to be verified again. Only a verified contact can be made primary; it takes the flag from the customer's other contact of its type.
+// @Tags Contacts
+// @Accept json
+// @Produce json
+// @Param customerID path string true "Customer ID or public UUID"
+// @Param contactID path int true "Contact ID"
+// @Param request body dto.UpdateContactRequest true "Contact"
+// @Success 200 {object} dto.ContactResponse "Contact updated"
+// @Failure 400 {object} dto.ErrorResponse "Invalid value"
+// @Failure 404 {object} dto.ErrorResponse "Contact not found"
+// @Failure 409 {object} dto.ErrorResponse "Contact is unverified and cannot be primary, or the customer already has the value"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /customers/{customerID}/contacts/{contactID} [put]
+// @Security BearerAuth
+func (h *ContactHandler) UpdateContact(w http.ResponseWriter, r *http.Request) {
+	customerID, contactID, err := h.contactFromURL(r)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	var req dto.UpdateContactRequest
+	if err := decodeJSON(r, &req); err != nil {
+		h.logger.WarnContext(r.Context(), "Failed to decode request body", slog.Any("error", err))
+		respondError(w, fmt.Errorf("%w: %w", apperrors.ErrInvalidArgument, err))
+		return
+	}
+	if err := req.Validate(); err != nil {
+		respondError(w, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err))
+		return
+	}
+
+	updated, err := h.service.UpdateContact(r.Context(), customerID, contactID, req.Value, req.Primary)
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "Service failed to update contact", slog.Int64("contactID", contactID), slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, dto.NewContactResponse(updated))
+}
+
+// DeleteContact handles DELETE /customers/{customerID}/contacts/{contactID}
+// @Summary Delete a contact
+// @Description Removes the contact and any code pending for it.
+// @Tags Contacts
+// @Param customerID path string true "Customer ID or public UUID"
+// @Param contactID path int true "Contact ID"
+// @Success 204 "Contact deleted"
+// @Failure 404 {object} dto.ErrorResponse "Contact not found"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /customers/{customerID}/contacts/{contactID} [delete]
+// @Security BearerAuth
+func (h *ContactHandler) DeleteContact(w http.ResponseWriter, r *http.Request) {
+	customerID, contactID, err := h.contactFromURL(r)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	if err := h.service.DeleteContact(r.Context(), customerID, contactID); err != nil {
+		h.logger.WarnContext(r.Context(), "Service failed to delete contact", slog.Int64("contactID", contactID), slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SendVerification handles POST /customers/{customerID}/contacts/{contactID}/verification
+// @Summary Send a verification code
+// @Description Sends a new six-digit code to the contact by SMS or email, replacing any code sent before. The code can be confirmed for 15 minutes; a new one can be requested once a minute.
+// @Tags Contacts
+// @Produce json
+// @Param customerID path string true "Customer ID or public UUID"
+// @Param contactID path int true "Contact ID"
+// @Success 202 {object} dto.ContactVerificationResponse "Code sent"
+// @Failure 404 {object} dto.ErrorResponse "Contact not found"
+// @Failure 409 {object} dto.ErrorResponse "Contact is already verified, or a code was sent less than a minute ago"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Failure 503 {object} dto.ErrorResponse "The code could not be sent"
+// @Router /customers/{customerID}/contacts/{contactID}/verification [post]
+// @Security BearerAuth
+func (h *ContactHandler) SendVerification(w http.ResponseWriter, r *http.Request) {
+	customerID, contactID, err := h.contactFromURL(r)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	expiresAt, err := h.service.SendVerification(r.Context(), customerID, contactID)
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "Service failed to send verification code", slog.Int64("contactID", contactID), slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusAccepted, dto.ContactVerificationResponse{ContactID: strconv.FormatInt(contactID, 10), ExpiresAt: expiresAt})
+}
+
+// ConfirmVerification handles POST /customers/{customerID}/contacts/{contactID}/verification/confirm
+// @Summary Confirm a verification code
+// @Description Verifies the contact with the code last sent to it. The first verified contact of a type becomes primary. Five wrong codes cancel the code.
+// @Tags Contacts
+// @Accept json
+// @Produce json
+// @Param customerID path string true "Customer ID or public UUID"
+// @Param contactID path int true "Contact ID"
+// @Param request body dto.ConfirmContactRequest true "Code"
+// @Success 200 {object} dto.ContactResponse "Contact verified"
+// @Failure 400 {object} dto.ErrorResponse "Wrong code"
+// @Failure 404 {object} dto.ErrorResponse "Contact not found"
+// @Failure 409 {object} dto.ErrorResponse "No code pending, the code expired or was cancelled, or the contact is already verified"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /customers/{customerID}/contacts/{contactID}/verification/confirm [post]
+// @Security BearerAuth
+func (h *ContactHandler) ConfirmVerification(w http.ResponseWriter, r *http.Request) {
+	customerID, contactID, err := h.contactFromURL(r)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	var req dto.ConfirmContactRequest
+	if err := decodeJSON(r, &req); err != nil {
+		h.logger.WarnContext(r.Context(), "Failed to decode request body", slog.Any("error", err))
+		respondError(w, fmt.Errorf("%w: %w", apperrors.ErrInvalidArgument, err))
+		return
+	}
+	if err := req.Validate(); err != nil {
+		respondError(w, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err))
+		return
+	}
+
+	verified, err := h.service.ConfirmVerification(r.Context(), customerID, contactID, req.Code)
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "Service failed to confirm verification code", slog.Int64("contactID", contactID), slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, dto.NewContactResponse(verified))
+}
@@ -0,0 +1,167 @@
+package handler_test
+
+import (
+	"billing-engine/internal/api/handler"
+	"billing-engine/internal/api/handler/dto"
+	"billing-engine/internal/domain/contact"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockContactService struct {
+	mock.Mock
+}
+
+func (m *MockContactService) ListContacts(ctx context.Context, customerID int64) ([]contact.Contact, error) {
+	args := m.Called(ctx, customerID)
+	contacts, _ := args.Get(0).([]contact.Contact)
+	return contacts, args.Error(1)
+}
+
+func (m *MockContactService) AddContact(ctx context.Context, customerID int64, t contact.Type, value string) (*contact.Contact, error) {
+	args := m.Called(ctx, customerID, t, value)
+	c, _ := args.Get(0).(*contact.Contact)
+	return c, args.Error(1)
+}
+
+func (m *MockContactService) UpdateContact(ctx context.Context, customerID, contactID int64, value string, primary bool) (*contact.Contact, error) {
+	args := m.Called(ctx, customerID, contactID, value, primary)
+	c, _ := args.Get(0).(*contact.Contact)
+	return c, args.Error(1)
+}
+
+func (m *MockContactService) DeleteContact(ctx context.Context, customerID, contactID int64) error {
+	return m.Called(ctx, customerID, contactID).Error(0)
+}
+
+func (m *MockContactService) SendVerification(ctx context.Context, customerID, contactID int64) (time.Time, error) {
+	args := m.Called(ctx, customerID, contactID)
+	expiresAt, _ := args.Get(0).(time.Time)
+	return expiresAt, args.Error(1)
+}
+
+func (m *MockContactService) ConfirmVerification(ctx context.Context, customerID, contactID int64, code string) (*contact.Contact, error) {
+	args := m.Called(ctx, customerID, contactID, code)
+	c, _ := args.Get(0).(*contact.Contact)
+	return c, args.Error(1)
+}
+
+func newContactHandler(svc contact.Service) *handler.ContactHandler {
+	return handler.NewContactHandler(svc, new(MockCustomerService), slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestContactHandlerCreateContact(t *testing.T) {
+	t.Run("adds the contact", func(t *testing.T) {
+		svc := new(MockContactService)
+		svc.On("AddContact", mock.Anything, int64(7), contact.TypePhone, "+62 812 3456 7890").
+			Return(&contact.Contact{ID: 12, CustomerID: 7, Type: contact.TypePhone, Value: "+6281234567890"}, nil).Once()
+
+		req := withURLParams(httptest.NewRequest(http.MethodPost, "/customers/7/contacts",
+			strings.NewReader(`{"type":"phone","value":"+62 812 3456 7890"}`)), map[string]string{"customerID": "7"})
+		rr := httptest.NewRecorder()
+		newContactHandler(svc).CreateContact(rr, req)
+
+		require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+		var resp dto.ContactResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+		assert.Equal(t, "12", resp.ID)
+		assert.Equal(t, "+6281234567890", resp.Value)
+		assert.False(t, resp.Verified)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("rejects a missing value", func(t *testing.T) {
+		svc := new(MockContactService)
+
+		req := withURLParams(httptest.NewRequest(http.MethodPost, "/customers/7/contacts",
+			strings.NewReader(`{"type":"email"}`)), map[string]string{"customerID": "7"})
+		rr := httptest.NewRecorder()
+		newContactHandler(svc).CreateContact(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		svc.AssertNotCalled(t, "AddContact", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestContactHandlerSendVerification(t *testing.T) {
+	t.Run("answers with the expiry but not the code", func(t *testing.T) {
+		svc := new(MockContactService)
+		expiresAt := time.Date(2025, 3, 5, 9, 15, 0, 0, time.UTC)
+		svc.On("SendVerification", mock.Anything, int64(7), int64(13)).Return(expiresAt, nil).Once()
+
+		req := withURLParams(httptest.NewRequest(http.MethodPost, "/customers/7/contacts/13/verification", nil),
+			map[string]string{"customerID": "7", "contactID": "13"})
+		rr := httptest.NewRecorder()
+		newContactHandler(svc).SendVerification(rr, req)
+
+		require.Equal(t, http.StatusAccepted, rr.Code, rr.Body.String())
+		assert.JSONEq(t, `{"contactId":"13","expiresAt":"2025-03-05T09:15:00Z"}`, rr.Body.String())
+	})
+
+	t.Run("code sent moments ago", func(t *testing.T) {
+		svc := new(MockContactService)
+		svc.On("SendVerification", mock.Anything, int64(7), int64(13)).Return(time.Time{}, apperrors.ErrConflict).Once()
+
+		req := withURLParams(httptest.NewRequest(http.MethodPost, "/customers/7/contacts/13/verification", nil),
+			map[string]string{"customerID": "7", "contactID": "13"})
+		rr := httptest.NewRecorder()
+		newContactHandler(svc).SendVerification(rr, req)
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+	})
+}
+
+func TestContactHandlerConfirmVerification(t *testing.T) {
+	t.Run("verifies the contact", func(t *testing.T) {
+		svc := new(MockContactService)
+		svc.On("ConfirmVerification", mock.Anything, int64(7), int64(13), "482913").
+			Return(&contact.Contact{ID: 13, CustomerID: 7, Type: contact.TypeEmail, Value: "ayu@example.com", Verified: true, Primary: true}, nil).Once()
+
+		req := withURLParams(httptest.NewRequest(http.MethodPost, "/customers/7/contacts/13/verification/confirm",
+			strings.NewReader(`{"code":"482913"}`)), map[string]string{"customerID": "7", "contactID": "13"})
+		rr := httptest.NewRecorder()
+		newContactHandler(svc).ConfirmVerification(rr, req)
+
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var resp dto.ContactResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+		assert.True(t, resp.Verified)
+		assert.True(t, resp.Primary)
+	})
+
+	t.Run("wrong code", func(t *testing.T) {
+		svc := new(MockContactService)
+		svc.On("ConfirmVerification", mock.Anything, int64(7), int64(13), "111111").Return(nil, apperrors.ErrInvalidArgument).Once()
+
+		req := withURLParams(httptest.NewRequest(http.MethodPost, "/customers/7/contacts/13/verification/confirm",
+			strings.NewReader(`{"code":"111111"}`)), map[string]string{"customerID": "7", "contactID": "13"})
+		rr := httptest.NewRecorder()
+		newContactHandler(svc).ConfirmVerification(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestContactHandlerDeleteContactBadID(t *testing.T) {
+	svc := new(MockContactService)
+
+	req := withURLParams(httptest.NewRequest(http.MethodDelete, "/customers/7/contacts/abc", nil),
+		map[string]string{"customerID": "7", "contactID": "abc"})
+	rr := httptest.NewRecorder()
+	newContactHandler(svc).DeleteContact(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	svc.AssertNotCalled(t, "DeleteContact", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -12,6 +12,7 @@ import (
 	"strings"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 )
 
 type CustomerHandler struct {
@@ -31,11 +32,17 @@ func NewCustomerHandler(s customer.CustomerService, l *slog.Logger) *CustomerHan
 		logger:  l.With("component", "CustomerHandler"),
 	}
 }
-func getCustomerIDFromURL(r *http.Request) (int64, error) {
+
+// customerIDFromURL accepts either the numeric customer ID or the customer's
+// public UUID and returns the internal ID.
+func (h *CustomerHandler) customerIDFromURL(r *http.Request) (int64, error) {
 	idStr := chi.URLParam(r, "customerID")
 	if idStr == "" {
 		return 0, fmt.Errorf("%w: customerID not found in URL path", apperrors.ErrInvalidArgument)
 	}
+	if publicID, err := uuid.Parse(idStr); err == nil {
+		return h.service.ResolveCustomerID(r.Context(), publicID)
+	}
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil || id <= 0 {
 		return 0, fmt.Errorf("%w: invalid customerID format in URL path: %s", apperrors.ErrInvalidArgument, idStr)
@@ -45,13 +52,14 @@ func getCustomerIDFromURL(r *http.Request) (int64, error) {
 
 // CreateCustomer handles POST /customers
 // @Summary Create a new customer
-// @Description Creates a new customer record with name and address.
+// @Description Creates a new customer record with name and address. A customer that repeats an active one is refused with 409 and the matches in details.candidateIds, unless force is set.
 // @Tags Customers
 // @Accept json
 // @Produce json
 // @Param request body dto.CreateCustomerRequest true "Customer creation request"
 // @Success 201 {object} dto.CustomerResponse "Customer successfully created"
 // @Failure 400 {object} dto.ErrorResponse "Invalid request payload (e.g., empty name/address)"
+// @Failure 409 {object} dto.ErrorResponse "Customer with this name and address already exists"
 // @Failure 500 {object} dto.ErrorResponse "Internal server error during creation"
 // @Router /customers [post]
 // @Security BearerAuth
@@ -62,7 +70,7 @@ func (h *CustomerHandler) CreateCustomer(w http.ResponseWriter, r *http.Request)
 	var req dto.CreateCustomerRequest
 	if err := decodeJSON(r, &req); err != nil {
 		h.logger.WarnContext(r.Context(), "Failed to decode request body", slog.Any("error", err))
-		respondError(w, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err))
+		respondError(w, fmt.Errorf("%w: %w", apperrors.ErrInvalidArgument, err))
 		return
 	}
 	if strings.TrimSpace(req.Name) == "" || strings.TrimSpace(req.Address) == "" {
@@ -70,10 +78,15 @@ func (h *CustomerHandler) CreateCustomer(w http.ResponseWriter, r *http.Request)
 		respondError(w, fmt.Errorf("%w: name and address cannot be empty", apperrors.ErrInvalidArgument))
 		return
 	}
+	if err := req.Validate(); err != nil {
+		h.logger.WarnContext(r.Context(), "Validation failed", slog.Any("error", err))
+		respondError(w, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err))
+		return
+	}
 	h.logger.DebugContext(r.Context(), "Request validation passed")
 
 	h.logger.DebugContext(r.Context(), "Calling customer service CreateNewCustomer")
-	createdCustomer, err := h.service.CreateNewCustomer(r.Context(), req.Name, req.Address)
+	createdCustomer, err := h.service.CreateNewCustomer(r.Context(), req.Name, req.Address, req.ExternalRef, req.PublicUUID(), req.Force)
 	if err != nil {
 		h.logger.ErrorContext(r.Context(), "Service failed to create customer", slog.Any("error", err))
 		respondError(w, err)
@@ -99,7 +112,7 @@ func (h *CustomerHandler) CreateCustomer(w http.ResponseWriter, r *http.Request)
 // @Security BearerAuth
 func (h *CustomerHandler) GetCustomer(w http.ResponseWriter, r *http.Request) {
 
-	customerID, err := getCustomerIDFromURL(r)
+	customerID, err := h.customerIDFromURL(r)
 	if err != nil {
 		h.logger.WarnContext(r.Context(), "Failed to get customer ID from URL", slog.Any("error", err))
 		respondError(w, err) // Pass logger to respondError
@@ -113,7 +126,7 @@ func (h *CustomerHandler) GetCustomer(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 
 		level := slog.LevelWarn
-		if !errors.Is(err, customer.ErrNotFound) && !errors.Is(err, apperrors.ErrNotFound) {
+		if !errors.Is(err, apperrors.ErrNotFound) {
 			level = slog.LevelError
 		}
 		h.logger.Log(r.Context(), level, "Service failed to get customer", slog.Any("error", err))
@@ -132,7 +145,10 @@ func (h *CustomerHandler) GetCustomer(w http.ResponseWriter, r *http.Request) {
 // @Tags Customers
 // @Produce json
 // @Param active query bool false "Filter by active status (behaviour depends on service implementation)" Example(true)
+// @Param If-None-Match header string false "ETag from an earlier response"
 // @Success 200 {array} dto.CustomerResponse "List of customers"
+// @Header 200 {string} ETag "Changes whenever the list changes"
+// @Success 304 "List unchanged since the ETag in If-None-Match"
 // @Failure 500 {object} dto.ErrorResponse "Internal server error"
 // @Router /customers [get]
 // @Security BearerAuth
@@ -154,7 +170,7 @@ func (h *CustomerHandler) ListCustomers(w http.ResponseWriter, r *http.Request)
 	}
 
 	h.logger.InfoContext(r.Context(), "Customers listed successfully", slog.Int("count", len(resp)))
-	respondJSON(w, http.StatusOK, resp)
+	respondCacheableJSON(w, r, resp)
 }
 
 // UpdateCustomerAddress handles PUT /customers/{customerID}/address
@@ -173,7 +189,7 @@ func (h *CustomerHandler) ListCustomers(w http.ResponseWriter, r *http.Request)
 // @Security BearerAuth
 func (h *CustomerHandler) UpdateCustomerAddress(w http.ResponseWriter, r *http.Request) {
 
-	customerID, err := getCustomerIDFromURL(r)
+	customerID, err := h.customerIDFromURL(r)
 	if err != nil {
 		h.logger.WarnContext(r.Context(), "Failed to get customer ID from URL", slog.Any("error", err))
 		respondError(w, err)
@@ -185,7 +201,7 @@ func (h *CustomerHandler) UpdateCustomerAddress(w http.ResponseWriter, r *http.R
 	var req dto.UpdateCustomerAddressRequest
 	if err := decodeJSON(r, &req); err != nil {
 		h.logger.WarnContext(r.Context(), "Failed to decode request body", slog.Any("error", err))
-		respondError(w, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err))
+		respondError(w, fmt.Errorf("%w: %w", apperrors.ErrInvalidArgument, err))
 		return
 	}
 
@@ -229,7 +245,7 @@ func (h *CustomerHandler) UpdateCustomerAddress(w http.ResponseWriter, r *http.R
 // @Security BearerAuth
 func (h *CustomerHandler) AssignLoanToCustomer(w http.ResponseWriter, r *http.Request) {
 
-	customerID, err := getCustomerIDFromURL(r)
+	customerID, err := h.customerIDFromURL(r)
 	if err != nil {
 		h.logger.WarnContext(r.Context(), "Failed to get customer ID from URL", slog.Any("error", err))
 		respondError(w, err)
@@ -241,7 +257,7 @@ func (h *CustomerHandler) AssignLoanToCustomer(w http.ResponseWriter, r *http.Re
 	var req dto.AssignLoanRequest
 	if err := decodeJSON(r, &req); err != nil {
 		h.logger.WarnContext(r.Context(), "Failed to decode request body", slog.Any("error", err))
-		respondError(w, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err))
+		respondError(w, fmt.Errorf("%w: %w", apperrors.ErrInvalidArgument, err))
 		return
 	}
 
@@ -286,7 +302,7 @@ func (h *CustomerHandler) AssignLoanToCustomer(w http.ResponseWriter, r *http.Re
 // @Security BearerAuth
 func (h *CustomerHandler) UpdateDelinquency(w http.ResponseWriter, r *http.Request) {
 
-	customerID, err := getCustomerIDFromURL(r)
+	customerID, err := h.customerIDFromURL(r)
 	if err != nil {
 		h.logger.WarnContext(r.Context(), "Failed to get customer ID from URL", slog.Any("error", err))
 		respondError(w, err)
@@ -298,7 +314,7 @@ func (h *CustomerHandler) UpdateDelinquency(w http.ResponseWriter, r *http.Reque
 	var req dto.UpdateDelinquencyRequest
 	if err := decodeJSON(r, &req); err != nil {
 		h.logger.WarnContext(r.Context(), "Failed to decode request body", slog.Any("error", err))
-		respondError(w, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err))
+		respondError(w, fmt.Errorf("%w: %w", apperrors.ErrInvalidArgument, err))
 		return
 	}
 
@@ -335,7 +351,7 @@ func (h *CustomerHandler) UpdateDelinquency(w http.ResponseWriter, r *http.Reque
 // @Security BearerAuth
 func (h *CustomerHandler) DeactivateCustomer(w http.ResponseWriter, r *http.Request) {
 
-	customerID, err := getCustomerIDFromURL(r)
+	customerID, err := h.customerIDFromURL(r)
 	if err != nil {
 		h.logger.WarnContext(r.Context(), "Failed to get customer ID from URL", slog.Any("error", err))
 		respondError(w, err)
@@ -375,7 +391,7 @@ func (h *CustomerHandler) DeactivateCustomer(w http.ResponseWriter, r *http.Requ
 // @Security BearerAuth
 func (h *CustomerHandler) ReactivateCustomer(w http.ResponseWriter, r *http.Request) {
 
-	customerID, err := getCustomerIDFromURL(r)
+	customerID, err := h.customerIDFromURL(r)
 	if err != nil {
 		h.logger.WarnContext(r.Context(), "Failed to get customer ID from URL", slog.Any("error", err))
 		respondError(w, err)
@@ -400,26 +416,35 @@ func (h *CustomerHandler) ReactivateCustomer(w http.ResponseWriter, r *http.Requ
 	respondJSON(w, http.StatusNoContent, nil)
 }
 
-// FindCustomerByLoan handles GET /customers?loan_id={loanID}
-// @Summary Find customer by loan ID
-// @Description Retrieves the customer associated with a specific loan ID.
+// FindCustomerByLoan handles GET /customers?loan_id={loanID} and GET /customers?external_ref={ref}
+// @Summary Find customer by loan ID or external reference
+// @Description Retrieves the customer associated with a specific loan ID, or the customer created with the given external reference. external_ref takes precedence when both are given.
 // @Tags Customers
 // @Produce json
-// @Param loan_id query int true "Loan ID to search for" Minimum(1)
+// @Param loan_id query int false "Loan ID to search for" Minimum(1)
+// @Param external_ref query string false "External reference supplied on creation"
+// @Param If-None-Match header string false "ETag from an earlier response"
 // @Success 200 {object} dto.CustomerResponse "Customer details retrieved"
-// @Failure 400 {object} dto.ErrorResponse "Invalid or missing loan_id query parameter"
-// @Failure 404 {object} dto.ErrorResponse "Customer not found for the given loan ID"
+// @Header 200 {string} ETag "Changes whenever the customer changes"
+// @Success 304 "Customer unchanged since the ETag in If-None-Match"
+// @Failure 400 {object} dto.ErrorResponse "Invalid or missing loan_id/external_ref query parameter"
+// @Failure 404 {object} dto.ErrorResponse "Customer not found"
 // @Failure 500 {object} dto.ErrorResponse "Internal server error"
 // @Router /customers [get]
 // @Security BearerAuth
 func (h *CustomerHandler) FindCustomerByLoan(w http.ResponseWriter, r *http.Request) {
 
+	if externalRef := strings.TrimSpace(r.URL.Query().Get("external_ref")); externalRef != "" {
+		h.findCustomerByExternalRef(w, r, externalRef)
+		return
+	}
+
 	h.logger.DebugContext(r.Context(), "Received find customer by loan request")
 
 	loanIDStr := r.URL.Query().Get("loan_id")
 	if loanIDStr == "" {
 		h.logger.WarnContext(r.Context(), "Missing loan_id query parameter")
-		respondError(w, fmt.Errorf("%w: missing required query parameter 'loan_id'", apperrors.ErrInvalidArgument))
+		respondError(w, fmt.Errorf("%w: missing required query parameter 'loan_id' or 'external_ref'", apperrors.ErrInvalidArgument))
 		return
 	}
 	loanID, err := strconv.ParseInt(loanIDStr, 10, 64)
@@ -435,7 +460,7 @@ func (h *CustomerHandler) FindCustomerByLoan(w http.ResponseWriter, r *http.Requ
 	domainCustomer, err := h.service.FindCustomerByLoan(r.Context(), loanID)
 	if err != nil {
 		level := slog.LevelWarn
-		if !errors.Is(err, customer.ErrNotFound) && !errors.Is(err, apperrors.ErrNotFound) {
+		if !errors.Is(err, apperrors.ErrNotFound) {
 			level = slog.LevelError
 		}
 		h.logger.Log(r.Context(), level, "Service failed to find customer by loan", slog.Any("error", err))
@@ -445,5 +470,194 @@ func (h *CustomerHandler) FindCustomerByLoan(w http.ResponseWriter, r *http.Requ
 
 	resp := dto.NewCustomerResponse(domainCustomer)
 	h.logger.InfoContext(r.Context(), "Customer found successfully by loan ID", slog.String("customerID", resp.CustomerID))
-	respondJSON(w, http.StatusOK, resp)
+	respondCacheableJSON(w, r, resp)
+}
+
+func (h *CustomerHandler) findCustomerByExternalRef(w http.ResponseWriter, r *http.Request, externalRef string) {
+
+	h.logger.DebugContext(r.Context(), "Calling customer service FindCustomerByExternalRef")
+	domainCustomer, err := h.service.FindCustomerByExternalRef(r.Context(), externalRef)
+	if err != nil {
+		level := slog.LevelWarn
+		if !errors.Is(err, apperrors.ErrNotFound) {
+			level = slog.LevelError
+		}
+		h.logger.Log(r.Context(), level, "Service failed to find customer by external reference", slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+
+	resp := dto.NewCustomerResponse(domainCustomer)
+	h.logger.InfoContext(r.Context(), "Customer found successfully by external reference", slog.String("customerID", resp.CustomerID))
+	respondCacheableJSON(w, r, resp)
+}
+
+// GetPreferences handles GET /customers/{customerID}/preferences
+// @Summary Get customer communication preferences
+// @Description Returns the customer's preferred channel and language and whether they opted out of marketing or transactional messages. A customer who never set preferences gets the defaults: no preferred channel or language and no opt-outs.
+// @Tags Customers
+// @Produce json
+// @Param customerID path int true "Customer ID" Minimum(1)
+// @Success 200 {object} dto.PreferencesResponse "Customer preferences"
+// @Failure 400 {object} dto.ErrorResponse "Invalid customer ID"
+// @Failure 404 {object} dto.ErrorResponse "Customer not found"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /customers/{customerID}/preferences [get]
+// @Security BearerAuth
+func (h *CustomerHandler) GetPreferences(w http.ResponseWriter, r *http.Request) {
+	customerID, err := h.customerIDFromURL(r)
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "Failed to get customer ID from URL", slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+
+	prefs, err := h.service.GetPreferences(r.Context(), customerID)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, dto.NewPreferencesResponse(prefs))
+}
+
+// UpdatePreferences handles PUT /customers/{customerID}/preferences
+// @Summary Replace customer communication preferences
+// @Description Replaces the customer's communication preferences and publishes them to notify-service, which applies them to every message it sends afterwards. preferredChannel is sms or email; language is a language tag such as id or en-US. Omitted fields are reset to their defaults.
+// @Tags Customers
+// @Accept json
+// @Produce json
+// @Param customerID path int true "Customer ID" Minimum(1)
+// @Param request body dto.UpdatePreferencesRequest true "New preferences"
+// @Success 200 {object} dto.PreferencesResponse "Preferences stored"
+// @Failure 400 {object} dto.ErrorResponse "Invalid customer ID, unknown channel or malformed language"
+// @Failure 404 {object} dto.ErrorResponse "Customer not found"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /customers/{customerID}/preferences [put]
+// @Security BearerAuth
+func (h *CustomerHandler) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	customerID, err := h.customerIDFromURL(r)
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "Failed to get customer ID from URL", slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+
+	var req dto.UpdatePreferencesRequest
+	if err := decodeJSON(r, &req); err != nil {
+		h.logger.WarnContext(r.Context(), "Failed to decode request body", slog.Any("error", err))
+		respondError(w, fmt.Errorf("%w: %w", apperrors.ErrInvalidArgument, err))
+		return
+	}
+
+	prefs, err := h.service.UpdatePreferences(r.Context(), req.Preferences(customerID))
+	if err != nil {
+		level := slog.LevelWarn
+		if !errors.Is(err, apperrors.ErrNotFound) && !errors.Is(err, apperrors.ErrInvalidArgument) {
+			level = slog.LevelError
+		}
+		h.logger.Log(r.Context(), level, "Service failed to update customer preferences", slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	h.logger.InfoContext(r.Context(), "Customer preferences updated successfully")
+	respondJSON(w, http.StatusOK, dto.NewPreferencesResponse(prefs))
+}
+
+// UpdateRiskScore handles PUT /customers/{customerID}/risk-score
+// @Summary Store a customer's credit bureau score
+// @Description Stores the score and grade a credit bureau gave the customer, replacing the previous ones. The bureau integration calls this after billing-engine asked it to score the customer on a customer.risk_score.requested event. score is between 0 and 1000; grade is up to 8 letters, digits, + or -, such as B+, and is upper-cased. New loans are checked against the credit limit of the stored grade.
+// @Tags Customers
+// @Accept json
+// @Produce json
+// @Param customerID path int true "Customer ID" Minimum(1)
+// @Param request body dto.UpdateRiskScoreRequest true "Bureau assessment"
+// @Success 200 {object} dto.CustomerResponse "Customer with the stored score"
+// @Failure 400 {object} dto.ErrorResponse "Invalid customer ID, score, grade or a scoredAt in the future"
+// @Failure 403 {object} dto.ErrorResponse "Token lacks the admin scope"
+// @Failure 404 {object} dto.ErrorResponse "Customer not found"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /customers/{customerID}/risk-score [put]
+// @Security BearerAuth
+func (h *CustomerHandler) UpdateRiskScore(w http.ResponseWriter, r *http.Request) {
+	customerID, err := h.customerIDFromURL(r)
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "Failed to get customer ID from URL", slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+
+	var req dto.UpdateRiskScoreRequest
+	if err := decodeJSON(r, &req); err != nil {
+		h.logger.WarnContext(r.Context(), "Failed to decode request body", slog.Any("error", err))
+		respondError(w, fmt.Errorf("%w: %w", apperrors.ErrInvalidArgument, err))
+		return
+	}
+	if err := req.Validate(); err != nil {
+		h.logger.WarnContext(r.Context(), "Validation failed", slog.Any("error", err))
+		respondError(w, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err))
+		return
+	}
+
+	cust, err := h.service.UpdateRiskScore(r.Context(), req.RiskAssessment(customerID))
+	if err != nil {
+		level := slog.LevelWarn
+		if !errors.Is(err, apperrors.ErrNotFound) && !errors.Is(err, apperrors.ErrInvalidArgument) {
+			level = slog.LevelError
+		}
+		h.logger.Log(r.Context(), level, "Service failed to update customer risk score", slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	h.logger.InfoContext(r.Context(), "Customer risk score updated successfully")
+	respondJSON(w, http.StatusOK, dto.NewCustomerResponse(cust))
+}
+
+// MergeCustomer handles POST /customers/{customerID}/merge
+// @Summary Merge a duplicate customer into this one
+// @Description Merges the customer sourceId, typically a duplicate created by an import, into the customer in the path in one transaction. The source's loan, with its schedule and payments, its notes, attachments, mandates, collection assignments, archived loans and recorded events are moved to the target. The source is deactivated and keeps a mergedIntoId pointing at the target. The merge is refused with 409 when either customer was already merged, the target is inactive, both hold a loan or both have an active direct debit mandate. Publishes customer.merged and a customer.updated event for each customer.
+// @Tags Customers
+// @Accept json
+// @Produce json
+// @Param customerID path int true "Customer ID of the target" Minimum(1)
+// @Param request body dto.MergeCustomerRequest true "Duplicate customer to merge"
+// @Success 200 {object} dto.CustomerMergeResponse "Both customers after the merge"
+// @Failure 400 {object} dto.ErrorResponse "Invalid customer ID or a customer merged into itself"
+// @Failure 403 {object} dto.ErrorResponse "Token lacks the admin scope"
+// @Failure 404 {object} dto.ErrorResponse "Customer not found"
+// @Failure 409 {object} dto.ErrorResponse "The customers cannot be merged"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /customers/{customerID}/merge [post]
+// @Security BearerAuth
+func (h *CustomerHandler) MergeCustomer(w http.ResponseWriter, r *http.Request) {
+	customerID, err := h.customerIDFromURL(r)
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "Failed to get customer ID from URL", slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+
+	var req dto.MergeCustomerRequest
+	if err := decodeJSON(r, &req); err != nil {
+		h.logger.WarnContext(r.Context(), "Failed to decode request body", slog.Any("error", err))
+		respondError(w, fmt.Errorf("%w: %w", apperrors.ErrInvalidArgument, err))
+		return
+	}
+	if err := req.Validate(); err != nil {
+		h.logger.WarnContext(r.Context(), "Validation failed", slog.Any("error", err))
+		respondError(w, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err))
+		return
+	}
+
+	merge, err := h.service.MergeCustomers(r.Context(), customerID, req.SourceID)
+	if err != nil {
+		level := slog.LevelWarn
+		if !errors.Is(err, apperrors.ErrNotFound) && !errors.Is(err, apperrors.ErrInvalidArgument) && !errors.Is(err, apperrors.ErrConflict) {
+			level = slog.LevelError
+		}
+		h.logger.Log(r.Context(), level, "Service failed to merge customers", slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	h.logger.InfoContext(r.Context(), "Customers merged successfully", slog.Int64("targetID", customerID), slog.Int64("sourceID", req.SourceID))
+	respondJSON(w, http.StatusOK, dto.NewCustomerMergeResponse(merge))
 }
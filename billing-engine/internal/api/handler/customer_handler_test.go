@@ -8,13 +8,16 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -23,12 +26,12 @@ type MockCustomerService struct {
 	mock.Mock
 }
 
-func (_m *MockCustomerService) CreateNewCustomer(ctx context.Context, name string, address string) (*customer.Customer, error) {
-	ret := _m.Called(ctx, name, address)
+func (_m *MockCustomerService) CreateNewCustomer(ctx context.Context, name string, address string, externalRef string, publicID uuid.UUID, force bool) (*customer.Customer, error) {
+	ret := _m.Called(ctx, name, address, externalRef, publicID, force)
 
 	var r0 *customer.Customer
-	if rf, ok := ret.Get(0).(func(context.Context, string, string) *customer.Customer); ok {
-		r0 = rf(ctx, name, address)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, uuid.UUID, bool) *customer.Customer); ok {
+		r0 = rf(ctx, name, address, externalRef, publicID, force)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*customer.Customer)
@@ -36,8 +39,8 @@ func (_m *MockCustomerService) CreateNewCustomer(ctx context.Context, name strin
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
-		r1 = rf(ctx, name, address)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string, uuid.UUID, bool) error); ok {
+		r1 = rf(ctx, name, address, externalRef, publicID, force)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -45,6 +48,61 @@ func (_m *MockCustomerService) CreateNewCustomer(ctx context.Context, name strin
 	return r0, r1
 }
 
+func (_m *MockCustomerService) FindCustomerByExternalRef(ctx context.Context, externalRef string) (*customer.Customer, error) {
+	ret := _m.Called(ctx, externalRef)
+
+	var r0 *customer.Customer
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*customer.Customer)
+	}
+	return r0, ret.Error(1)
+}
+
+func (_m *MockCustomerService) ResolveCustomerID(ctx context.Context, publicID uuid.UUID) (int64, error) {
+	ret := _m.Called(ctx, publicID)
+	return ret.Get(0).(int64), ret.Error(1)
+}
+
+func (_m *MockCustomerService) GetPreferences(ctx context.Context, customerID int64) (*customer.Preferences, error) {
+	ret := _m.Called(ctx, customerID)
+	var r0 *customer.Preferences
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*customer.Preferences)
+	}
+	return r0, ret.Error(1)
+}
+
+func (_m *MockCustomerService) UpdatePreferences(ctx context.Context, p customer.Preferences) (*customer.Preferences, error) {
+	ret := _m.Called(ctx, p)
+	var r0 *customer.Preferences
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*customer.Preferences)
+	}
+	return r0, ret.Error(1)
+}
+
+func (_m *MockCustomerService) RequestRiskScore(ctx context.Context, cust *customer.Customer, reason customer.RiskScoreReason, principal float64) {
+	_m.Called(ctx, cust, reason, principal)
+}
+
+func (_m *MockCustomerService) UpdateRiskScore(ctx context.Context, a customer.RiskAssessment) (*customer.Customer, error) {
+	ret := _m.Called(ctx, a)
+	var r0 *customer.Customer
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*customer.Customer)
+	}
+	return r0, ret.Error(1)
+}
+
+func (_m *MockCustomerService) MergeCustomers(ctx context.Context, targetID, sourceID int64) (*customer.Merge, error) {
+	ret := _m.Called(ctx, targetID, sourceID)
+	var r0 *customer.Merge
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*customer.Merge)
+	}
+	return r0, ret.Error(1)
+}
+
 func (_m *MockCustomerService) GetCustomer(ctx context.Context, customerID int64) (*customer.Customer, error) {
 	ret := _m.Called(ctx, customerID)
 
@@ -89,6 +147,16 @@ func (_m *MockCustomerService) ListActiveCustomers(ctx context.Context) ([]*cust
 	return r0, r1
 }
 
+func (_m *MockCustomerService) ListActiveCustomersPage(ctx context.Context, afterID int64, limit int) ([]*customer.Customer, error) {
+	ret := _m.Called(ctx, afterID, limit)
+
+	var r0 []*customer.Customer
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*customer.Customer)
+	}
+	return r0, ret.Error(1)
+}
+
 func (_m *MockCustomerService) UpdateCustomerAddress(ctx context.Context, customerID int64, newAddress string) error {
 	ret := _m.Called(ctx, customerID, newAddress)
 
@@ -128,6 +196,33 @@ func (_m *MockCustomerService) UpdateDelinquency(ctx context.Context, customerID
 	return r0
 }
 
+func (_m *MockCustomerService) UpdateDelinquencyBulk(ctx context.Context, updates []customer.CustomerDelinquency) ([]*customer.Customer, error) {
+	ret := _m.Called(ctx, updates)
+
+	var r0 []*customer.Customer
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*customer.Customer)
+	}
+
+	return r0, ret.Error(1)
+}
+
+func (_m *MockCustomerService) NotifyDelinquency(ctx context.Context, notices []customer.DelinquencyNotice) ([]customer.DelinquencyNotice, error) {
+	ret := _m.Called(ctx, notices)
+
+	var r0 []customer.DelinquencyNotice
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]customer.DelinquencyNotice)
+	}
+
+	return r0, ret.Error(1)
+}
+
+func (_m *MockCustomerService) ClearDelinquencyNotices(ctx context.Context, customerIDs []int64) error {
+	ret := _m.Called(ctx, customerIDs)
+	return ret.Error(0)
+}
+
 func (_m *MockCustomerService) DeactivateCustomer(ctx context.Context, customerID int64) error {
 	ret := _m.Called(ctx, customerID)
 
@@ -189,7 +284,7 @@ func TestCreateCustomer(t *testing.T) {
 		rec := httptest.NewRecorder()
 
 		mockCustomer := &customer.Customer{CustomerID: 1, Name: "John Doe", Address: "123 Main St"}
-		mockService.On("CreateNewCustomer", mock.Anything, reqBody.Name, reqBody.Address).Return(mockCustomer, nil)
+		mockService.On("CreateNewCustomer", mock.Anything, reqBody.Name, reqBody.Address, "", uuid.Nil, false).Return(mockCustomer, nil)
 
 		handler.CreateCustomer(rec, req)
 
@@ -201,6 +296,74 @@ func TestCreateCustomer(t *testing.T) {
 		mockService.AssertExpectations(t)
 	})
 
+	t.Run("client supplied public ID", func(t *testing.T) {
+		publicID := uuid.New()
+		reqBody := dto.CreateCustomerRequest{Name: "Ann Doe", Address: "2 Side St", PublicID: publicID.String()}
+		reqBodyBytes, _ := json.Marshal(reqBody)
+		req := httptest.NewRequest(http.MethodPost, "/customers", bytes.NewReader(reqBodyBytes))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		mockService.On("CreateNewCustomer", mock.Anything, reqBody.Name, reqBody.Address, "", publicID, false).
+			Return(&customer.Customer{CustomerID: 2, PublicID: publicID, Name: reqBody.Name}, nil).Once()
+
+		handler.CreateCustomer(rec, req)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		var resp dto.CustomerResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, publicID.String(), resp.PublicID)
+	})
+
+	t.Run("duplicate external reference", func(t *testing.T) {
+		reqBody := dto.CreateCustomerRequest{Name: "Jane Doe", Address: "1 Side St", ExternalRef: "CRM-0001"}
+		reqBodyBytes, _ := json.Marshal(reqBody)
+		req := httptest.NewRequest(http.MethodPost, "/customers", bytes.NewReader(reqBodyBytes))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		mockService.On("CreateNewCustomer", mock.Anything, reqBody.Name, reqBody.Address, reqBody.ExternalRef, uuid.Nil, false).
+			Return(nil, fmt.Errorf("failed to save new customer: %w", apperrors.ErrAlreadyExists))
+
+		handler.CreateCustomer(rec, req)
+
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("duplicate name and address", func(t *testing.T) {
+		reqBody := dto.CreateCustomerRequest{Name: "Budi Santoso", Address: "Jl. Merdeka 5"}
+		reqBodyBytes, _ := json.Marshal(reqBody)
+		req := httptest.NewRequest(http.MethodPost, "/customers", bytes.NewReader(reqBodyBytes))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		mockService.On("CreateNewCustomer", mock.Anything, reqBody.Name, reqBody.Address, "", uuid.Nil, false).
+			Return(nil, apperrors.New(apperrors.CodeConflict, "a customer with this name and address already exists").WithMeta("candidateIds", "3,11")).Once()
+
+		handler.CreateCustomer(rec, req)
+
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		var resp dto.ErrorResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, "3,11", resp.Error.Details["candidateIds"])
+	})
+
+	t.Run("forced duplicate", func(t *testing.T) {
+		reqBody := dto.CreateCustomerRequest{Name: "Budi Santoso", Address: "Jl. Merdeka 5", Force: true}
+		reqBodyBytes, _ := json.Marshal(reqBody)
+		req := httptest.NewRequest(http.MethodPost, "/customers", bytes.NewReader(reqBodyBytes))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		mockService.On("CreateNewCustomer", mock.Anything, reqBody.Name, reqBody.Address, "", uuid.Nil, true).
+			Return(&customer.Customer{CustomerID: 12, Name: reqBody.Name}, nil).Once()
+
+		handler.CreateCustomer(rec, req)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+	})
+
 	t.Run("invalid payload", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPost, "/customers", bytes.NewReader([]byte(`{}`)))
 		req.Header.Set("Content-Type", "application/json")
@@ -213,6 +376,94 @@ func TestCreateCustomer(t *testing.T) {
 	})
 }
 
+func TestGetCustomerByPublicID(t *testing.T) {
+	mockService := new(MockCustomerService)
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	handler := handler.NewCustomerHandler(mockService, logger)
+	publicID := uuid.New()
+
+	newRequest := func(id string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/customers/"+id, nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("customerID", id)
+		return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	}
+
+	t.Run("resolves public ID", func(t *testing.T) {
+		mockService.On("ResolveCustomerID", mock.Anything, publicID).Return(int64(4), nil).Once()
+		mockService.On("GetCustomer", mock.Anything, int64(4)).
+			Return(&customer.Customer{CustomerID: 4, PublicID: publicID, Name: "John Doe"}, nil).Once()
+		rec := httptest.NewRecorder()
+
+		handler.GetCustomer(rec, newRequest(publicID.String()))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var resp dto.CustomerResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, "4", resp.CustomerID)
+		assert.Equal(t, publicID.String(), resp.PublicID)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("unknown public ID", func(t *testing.T) {
+		unknown := uuid.New()
+		mockService.On("ResolveCustomerID", mock.Anything, unknown).
+			Return(int64(0), fmt.Errorf("%w: customer with public ID %s not found", apperrors.ErrNotFound, unknown)).Once()
+		rec := httptest.NewRecorder()
+
+		handler.GetCustomer(rec, newRequest(unknown.String()))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		mockService.AssertNotCalled(t, "GetCustomer", mock.Anything, int64(0))
+	})
+}
+
+func TestFindCustomerByExternalRef(t *testing.T) {
+	mockService := new(MockCustomerService)
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	handler := handler.NewCustomerHandler(mockService, logger)
+	externalRef := "CRM-0001"
+
+	t.Run("success", func(t *testing.T) {
+		mockService.On("FindCustomerByExternalRef", mock.Anything, externalRef).
+			Return(&customer.Customer{CustomerID: 9, Name: "John Doe", ExternalRef: &externalRef}, nil).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/customers?external_ref="+externalRef, nil)
+		rec := httptest.NewRecorder()
+
+		handler.FindCustomerByLoan(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var resp dto.CustomerResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, "9", resp.CustomerID)
+		assert.Equal(t, externalRef, *resp.ExternalRef)
+		assert.NotEmpty(t, rec.Header().Get("ETag"))
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mockService.On("FindCustomerByExternalRef", mock.Anything, "missing").Return(nil, apperrors.ErrNotFound).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/customers?external_ref=missing", nil)
+		rec := httptest.NewRecorder()
+
+		handler.FindCustomerByLoan(rec, req)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("missing query parameters", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/customers", nil)
+		rec := httptest.NewRecorder()
+
+		handler.FindCustomerByLoan(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
 func TestGetCustomer(t *testing.T) {
 	mockService := new(MockCustomerService)
 	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
@@ -268,3 +519,244 @@ func TestGetCustomer(t *testing.T) {
 		mockService.AssertExpectations(t)
 	})
 }
+
+func TestGetPreferences(t *testing.T) {
+	mockService := new(MockCustomerService)
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	handler := handler.NewCustomerHandler(mockService, logger)
+
+	t.Run("defaults when never set", func(t *testing.T) {
+		mockService.On("GetPreferences", mock.Anything, int64(1)).Return(&customer.Preferences{CustomerID: 1}, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/customers/1/preferences", nil)
+		rec := httptest.NewRecorder()
+
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("customerID", "1")
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+		handler.GetPreferences(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"customerId":"1","marketingOptOut":false,"transactionalOptOut":false}`, rec.Body.String())
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("customer not found", func(t *testing.T) {
+		mockService.On("GetPreferences", mock.Anything, int64(2)).Return(nil, apperrors.ErrNotFound)
+
+		req := httptest.NewRequest(http.MethodGet, "/customers/2/preferences", nil)
+		rec := httptest.NewRecorder()
+
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("customerID", "2")
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+		handler.GetPreferences(rec, req)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestUpdatePreferences(t *testing.T) {
+	mockService := new(MockCustomerService)
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	handler := handler.NewCustomerHandler(mockService, logger)
+
+	newRequest := func(id, body string) *http.Request {
+		req := httptest.NewRequest(http.MethodPut, "/customers/"+id+"/preferences", bytes.NewBufferString(body))
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("customerID", id)
+		return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	}
+
+	t.Run("success", func(t *testing.T) {
+		updatedAt := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+		want := customer.Preferences{CustomerID: 1, PreferredChannel: "sms", Language: "id", MarketingOptOut: true}
+		stored := want
+		stored.UpdatedAt = updatedAt
+		mockService.On("UpdatePreferences", mock.Anything, want).Return(&stored, nil).Once()
+
+		rec := httptest.NewRecorder()
+		handler.UpdatePreferences(rec, newRequest("1", `{"preferredChannel":"sms","language":"id","marketingOptOut":true}`))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var resp dto.PreferencesResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, "sms", resp.PreferredChannel)
+		assert.True(t, resp.MarketingOptOut)
+		if assert.NotNil(t, resp.UpdatedAt) {
+			assert.True(t, updatedAt.Equal(*resp.UpdatedAt))
+		}
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("unknown channel", func(t *testing.T) {
+		mockService.On("UpdatePreferences", mock.Anything, mock.MatchedBy(func(p customer.Preferences) bool {
+			return p.PreferredChannel == "fax"
+		})).Return(nil, fmt.Errorf("%w: preferred channel must be \"sms\" or \"email\"", apperrors.ErrInvalidArgument)).Once()
+
+		rec := httptest.NewRecorder()
+		handler.UpdatePreferences(rec, newRequest("1", `{"preferredChannel":"fax"}`))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("malformed body", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.UpdatePreferences(rec, newRequest("3", `{"marketingOptOut":"yes"}`))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("customer not found", func(t *testing.T) {
+		mockService.On("UpdatePreferences", mock.Anything, customer.Preferences{CustomerID: 2}).Return(nil, apperrors.ErrNotFound).Once()
+
+		rec := httptest.NewRecorder()
+		handler.UpdatePreferences(rec, newRequest("2", `{}`))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestUpdateRiskScore(t *testing.T) {
+	mockService := new(MockCustomerService)
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	handler := handler.NewCustomerHandler(mockService, logger)
+
+	newRequest := func(id, body string) *http.Request {
+		req := httptest.NewRequest(http.MethodPut, "/customers/"+id+"/risk-score", bytes.NewBufferString(body))
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("customerID", id)
+		return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	}
+
+	t.Run("success", func(t *testing.T) {
+		scoredAt := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+		score, grade := 720, "B+"
+		want := customer.RiskAssessment{CustomerID: 1, Score: 720, Grade: "b+", ScoredAt: scoredAt}
+		mockService.On("UpdateRiskScore", mock.Anything, want).
+			Return(&customer.Customer{CustomerID: 1, Name: "Budi", Active: true, RiskScore: &score, RiskGrade: &grade, RiskScoredAt: &scoredAt}, nil).Once()
+
+		rec := httptest.NewRecorder()
+		handler.UpdateRiskScore(rec, newRequest("1", `{"score":720,"grade":"b+","scoredAt":"2025-03-01T08:00:00Z"}`))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var resp dto.CustomerResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, &score, resp.RiskScore)
+		assert.Equal(t, &grade, resp.RiskGrade)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("missing score or grade", func(t *testing.T) {
+		for _, body := range []string{`{"grade":"A"}`, `{"score":500,"grade":" "}`, `{"score":"high"}`} {
+			rec := httptest.NewRecorder()
+			handler.UpdateRiskScore(rec, newRequest("1", body))
+			assert.Equal(t, http.StatusBadRequest, rec.Code, body)
+		}
+	})
+
+	t.Run("score out of range", func(t *testing.T) {
+		mockService.On("UpdateRiskScore", mock.Anything, customer.RiskAssessment{CustomerID: 1, Score: 1200, Grade: "A"}).
+			Return(nil, fmt.Errorf("%w: risk score must be between 0 and 1000", apperrors.ErrInvalidArgument)).Once()
+
+		rec := httptest.NewRecorder()
+		handler.UpdateRiskScore(rec, newRequest("1", `{"score":1200,"grade":"A"}`))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("customer not found", func(t *testing.T) {
+		mockService.On("UpdateRiskScore", mock.Anything, customer.RiskAssessment{CustomerID: 2, Score: 500, Grade: "C"}).
+			Return(nil, apperrors.ErrNotFound).Once()
+
+		rec := httptest.NewRecorder()
+		handler.UpdateRiskScore(rec, newRequest("2", `{"score":500,"grade":"C"}`))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestMergeCustomer(t *testing.T) {
+	mockService := new(MockCustomerService)
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	handler := handler.NewCustomerHandler(mockService, logger)
+
+	newRequest := func(id, body string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/customers/"+id+"/merge", bytes.NewBufferString(body))
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("customerID", id)
+		return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	}
+
+	t.Run("success", func(t *testing.T) {
+		loanID, targetID := int64(77), int64(1)
+		mergedAt := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+		mockService.On("MergeCustomers", mock.Anything, int64(1), int64(2)).Return(&customer.Merge{
+			Target: &customer.Customer{CustomerID: 1, Name: "Budi", Active: true, LoanID: &loanID},
+			Source: &customer.Customer{CustomerID: 2, Name: "Budi", MergedIntoID: &targetID, MergedAt: &mergedAt},
+			LoanID: &loanID,
+			Moved:  customer.MergedRecords{Notes: 2, Events: 5},
+		}, nil).Once()
+
+		rec := httptest.NewRecorder()
+		handler.MergeCustomer(rec, newRequest("1", `{"sourceId":2}`))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var resp dto.CustomerMergeResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, "77", *resp.LoanID)
+		assert.Equal(t, "1", *resp.Source.MergedIntoID)
+		assert.Equal(t, dto.MergedRecordsResponse{Notes: 2, Events: 5}, resp.Moved)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("missing source", func(t *testing.T) {
+		for _, body := range []string{`{}`, `{"sourceId":-3}`, `{"sourceId":"2"}`} {
+			rec := httptest.NewRecorder()
+			handler.MergeCustomer(rec, newRequest("1", body))
+			assert.Equal(t, http.StatusBadRequest, rec.Code, body)
+		}
+	})
+
+	t.Run("already merged", func(t *testing.T) {
+		mockService.On("MergeCustomers", mock.Anything, int64(1), int64(3)).
+			Return(nil, fmt.Errorf("%w: customer 3 was merged into customer 4", customer.ErrAlreadyMerged)).Once()
+
+		rec := httptest.NewRecorder()
+		handler.MergeCustomer(rec, newRequest("1", `{"sourceId":3}`))
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestListCustomersETag(t *testing.T) {
+	mockService := new(MockCustomerService)
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	handler := handler.NewCustomerHandler(mockService, logger)
+
+	customers := []*customer.Customer{{CustomerID: 1, Name: "John Doe", Address: "123 Main St", Active: true}}
+	mockService.On("ListActiveCustomers", mock.Anything).Return(customers, nil).Twice()
+
+	rec := httptest.NewRecorder()
+	handler.ListCustomers(rec, httptest.NewRequest(http.MethodGet, "/customers", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	etag := rec.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	req := httptest.NewRequest(http.MethodGet, "/customers", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	handler.ListCustomers(rec, req)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Body.Bytes())
+	assert.Equal(t, etag, rec.Header().Get("ETag"))
+
+	customers = append(customers, &customer.Customer{CustomerID: 2, Name: "Jane Roe", Address: "9 Side St", Active: true})
+	mockService.On("ListActiveCustomers", mock.Anything).Return(customers, nil).Once()
+	rec = httptest.NewRecorder()
+	handler.ListCustomers(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEqual(t, etag, rec.Header().Get("ETag"))
+	mockService.AssertExpectations(t)
+}
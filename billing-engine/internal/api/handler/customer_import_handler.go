@@ -0,0 +1,283 @@
+package handler
+
+import (
+	"billing-engine/internal/api/handler/dto"
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/pkg/apperrors"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	importFormatCSV    = "csv"
+	importFormatNDJSON = "ndjson"
+	importFileField    = "file"
+
+	jobKindCustomerImport = "customer-import"
+)
+
+type CustomerImportHandler struct {
+	service  customer.ImportService
+	bulk     *BulkRunner
+	maxRows  int
+	maxBytes int64
+	logger   *slog.Logger
+}
+
+// NewCustomerImportHandler caps uploads at maxRows rows and maxBytes, zero
+// meaning no cap. Large uploads are imported as a job on bulk; a nil bulk
+// imports every upload within the request.
+func NewCustomerImportHandler(s customer.ImportService, bulk *BulkRunner, maxRows int, maxBytes int64, l *slog.Logger) *CustomerImportHandler {
+	if s == nil {
+		panic("customer import service cannot be nil")
+	}
+	if l == nil {
+		panic("logger cannot be nil")
+	}
+	h := &CustomerImportHandler{
+		service:  s,
+		bulk:     bulk,
+		maxRows:  maxRows,
+		maxBytes: maxBytes,
+		logger:   l.With("component", "CustomerImportHandler"),
+	}
+	registerBulkTask(bulk, jobKindCustomerImport, h.importRows)
+	return h
+}
+
+// ImportCustomers handles POST /customers/import
+// @Summary Import customers in bulk
+// @Description Accepts a CSV (text/csv, header row with name, address and external_ref) or NDJSON (application/x-ndjson) upload, either as the raw body or as the "file" field of a multipart form. Rows are validated, deduplicated by external reference and inserted in chunks; the response reports the outcome of every row. Uploads of more than bulk.syncMaxRows rows, or sent with `Prefer: respond-async`, are imported in the background: the answer is 202 with the job to poll at the Location header, whose result is the same report.
+// @Tags Customers
+// @Accept text/csv
+// @Accept application/x-ndjson
+// @Accept multipart/form-data
+// @Produce json
+// @Success 200 {object} dto.CustomerImportResponse "Per-row import report"
+// @Success 202 {object} dto.JobResponse "Import continues in the background"
+// @Failure 400 {object} dto.ErrorResponse "Unsupported, malformed or oversized upload"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Failure 503 {object} dto.ErrorResponse "Too many bulk jobs in progress, retry after the Retry-After delay"
+// @Router /customers/import [post]
+// @Security BearerAuth
+func (h *CustomerImportHandler) ImportCustomers(w http.ResponseWriter, r *http.Request) {
+	h.logger.DebugContext(r.Context(), "Received customer import request")
+
+	if h.maxBytes > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, h.maxBytes)
+	}
+
+	rows, err := h.readUpload(r)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			err = fmt.Errorf("%w: upload exceeds %d bytes", apperrors.ErrInvalidArgument, maxBytesErr.Limit)
+		}
+		h.logger.WarnContext(r.Context(), "Rejected customer import upload", slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	if len(rows) == 0 {
+		respondError(w, fmt.Errorf("%w: upload contains no customer rows", apperrors.ErrInvalidArgument))
+		return
+	}
+
+	respondBulk(h.bulk, w, r, jobKindCustomerImport, len(rows), rows, h.importRows)
+}
+
+func (h *CustomerImportHandler) importRows(ctx context.Context, rows []customer.ImportRow, progress func(int)) (any, error) {
+	report := h.service.ImportCustomers(ctx, rows, progress)
+	h.logger.InfoContext(ctx, "Customer import completed",
+		slog.Int("total", report.Total), slog.Int("created", report.Created))
+	return dto.NewCustomerImportResponse(report), nil
+}
+
+func (h *CustomerImportHandler) readUpload(r *http.Request) ([]customer.ImportRow, error) {
+	if r.Body == nil {
+		return nil, fmt.Errorf("%w: no request body", apperrors.ErrInvalidArgument)
+	}
+	defer r.Body.Close()
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: missing or invalid Content-Type", apperrors.ErrInvalidArgument)
+	}
+
+	if mediaType != "multipart/form-data" {
+		format, err := importFormat(mediaType, "")
+		if err != nil {
+			return nil, err
+		}
+		return h.parse(format, r.Body)
+	}
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid multipart body: %v", apperrors.ErrInvalidArgument, err)
+	}
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%w: multipart body has no %q field", apperrors.ErrInvalidArgument, importFileField)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid multipart body: %v", apperrors.ErrInvalidArgument, err)
+		}
+		if part.FormName() != importFileField {
+			part.Close()
+			continue
+		}
+		partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		format, err := importFormat(partType, part.FileName())
+		if err != nil {
+			return nil, err
+		}
+		defer part.Close()
+		return h.parse(format, part)
+	}
+}
+
+func importFormat(mediaType, filename string) (string, error) {
+	switch mediaType {
+	case "text/csv", "application/csv":
+		return importFormatCSV, nil
+	case "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return importFormatNDJSON, nil
+	}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return importFormatCSV, nil
+	case ".ndjson", ".jsonl":
+		return importFormatNDJSON, nil
+	}
+	return "", fmt.Errorf("%w: unsupported upload type %q, use text/csv or application/x-ndjson", apperrors.ErrInvalidArgument, mediaType)
+}
+
+func (h *CustomerImportHandler) parse(format string, body io.Reader) ([]customer.ImportRow, error) {
+	if format == importFormatCSV {
+		return h.parseCSV(body)
+	}
+	return h.parseNDJSON(body)
+}
+
+// parseCSV reads a header row and maps the name, address and external_ref
+// columns by name, so column order and extra columns do not matter.
+func (h *CustomerImportHandler) parseCSV(body io.Reader) ([]customer.ImportRow, error) {
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, csvError(err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\uFEFF")))
+		if name == "externalref" {
+			name = "external_ref"
+		}
+		columns[name] = i
+	}
+	for _, required := range []string{"name", "address", "external_ref"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("%w: CSV header is missing the %q column", apperrors.ErrInvalidArgument, required)
+		}
+	}
+
+	field := func(record []string, column string) string {
+		if i := columns[column]; i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+
+	var rows []customer.ImportRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, csvError(err)
+		}
+		if len(rows) == h.maxRows && h.maxRows > 0 {
+			return nil, fmt.Errorf("%w: upload exceeds %d rows", apperrors.ErrInvalidArgument, h.maxRows)
+		}
+		line, _ := reader.FieldPos(0)
+		rows = append(rows, customer.ImportRow{
+			Line:        line,
+			Name:        field(record, "name"),
+			Address:     field(record, "address"),
+			ExternalRef: field(record, "external_ref"),
+		})
+	}
+}
+
+func csvError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return err
+	}
+	return fmt.Errorf("%w: malformed CSV: %v", apperrors.ErrInvalidArgument, err)
+}
+
+func (h *CustomerImportHandler) parseNDJSON(body io.Reader) ([]customer.ImportRow, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var rows []customer.ImportRow
+	for line := 1; scanner.Scan(); line++ {
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+		if len(rows) == h.maxRows && h.maxRows > 0 {
+			return nil, fmt.Errorf("%w: upload exceeds %d rows", apperrors.ErrInvalidArgument, h.maxRows)
+		}
+
+		var row dto.CustomerImportRow
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&row); err != nil {
+			if readErr := scanner.Err(); readErr != nil {
+				// A failed read still yields the partial last line.
+				return nil, ndjsonReadError(readErr)
+			}
+			return nil, fmt.Errorf("%w: malformed NDJSON on line %d: %v", apperrors.ErrInvalidArgument, line, err)
+		}
+		rows = append(rows, customer.ImportRow{
+			Line:        line,
+			Name:        row.Name,
+			Address:     row.Address,
+			ExternalRef: row.ExternalRef,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, ndjsonReadError(err)
+	}
+	return rows, nil
+}
+
+func ndjsonReadError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return err
+	}
+	return fmt.Errorf("%w: could not read NDJSON upload: %v", apperrors.ErrInvalidArgument, err)
+}
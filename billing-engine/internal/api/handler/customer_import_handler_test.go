@@ -0,0 +1,219 @@
+package handler_test
+
+import (
+	"billing-engine/internal/api/handler"
+	"billing-engine/internal/api/handler/dto"
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/jobs"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockImportService struct {
+	mock.Mock
+}
+
+func (m *MockImportService) ImportCustomers(ctx context.Context, rows []customer.ImportRow, progress func(int)) *customer.ImportReport {
+	return m.Called(ctx, rows).Get(0).(*customer.ImportReport)
+}
+
+func newImportHandler(svc customer.ImportService, maxRows int, maxBytes int64) *handler.CustomerImportHandler {
+	return handler.NewCustomerImportHandler(svc, nil, maxRows, maxBytes, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func postImport(h *handler.CustomerImportHandler, contentType string, body io.Reader) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/customers/import", body)
+	req.Header.Set("Content-Type", contentType)
+	rec := httptest.NewRecorder()
+	h.ImportCustomers(rec, req)
+	return rec
+}
+
+func TestImportCustomersCSV(t *testing.T) {
+	svc := new(MockImportService)
+	svc.On("ImportCustomers", mock.Anything, []customer.ImportRow{
+		{Line: 2, Name: "Jane", Address: "1 Main St, Springfield", ExternalRef: "crm-1"},
+		{Line: 3, Name: "Bob", Address: "", ExternalRef: "crm-2"},
+	}).Return(&customer.ImportReport{
+		Total: 2, Created: 1, Invalid: 1,
+		Results: []customer.ImportResult{
+			{Line: 2, ExternalRef: "crm-1", Status: customer.ImportStatusCreated, CustomerID: 41},
+			{Line: 3, ExternalRef: "crm-2", Status: customer.ImportStatusInvalid, Error: "address cannot be empty"},
+		},
+	})
+
+	body := "external_ref,name,address,segment\ncrm-1,Jane,\"1 Main St, Springfield\",retail\ncrm-2,Bob\n"
+	rec := postImport(newImportHandler(svc, 100, 0), "text/csv; charset=utf-8", strings.NewReader(body))
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var resp dto.CustomerImportResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, 1, resp.Created)
+	require.Len(t, resp.Results, 2)
+	require.NotNil(t, resp.Results[0].CustomerID)
+	assert.Equal(t, "41", *resp.Results[0].CustomerID)
+	assert.Nil(t, resp.Results[1].CustomerID)
+	svc.AssertExpectations(t)
+}
+
+func TestImportCustomersNDJSON(t *testing.T) {
+	svc := new(MockImportService)
+	svc.On("ImportCustomers", mock.Anything, []customer.ImportRow{
+		{Line: 1, Name: "Jane", Address: "1 Main St", ExternalRef: "crm-1"},
+		{Line: 3, Name: "Bob", Address: "2 Main St", ExternalRef: "crm-2"},
+	}).Return(&customer.ImportReport{Total: 2})
+
+	body := `{"name":"Jane","address":"1 Main St","externalRef":"crm-1"}` + "\n\n" +
+		`{"name":"Bob","address":"2 Main St","externalRef":"crm-2"}` + "\n"
+	rec := postImport(newImportHandler(svc, 100, 0), "application/x-ndjson", strings.NewReader(body))
+
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	svc.AssertExpectations(t)
+}
+
+func TestImportCustomersMultipart(t *testing.T) {
+	svc := new(MockImportService)
+	svc.On("ImportCustomers", mock.Anything, []customer.ImportRow{
+		{Line: 2, Name: "Jane", Address: "1 Main St", ExternalRef: "crm-1"},
+	}).Return(&customer.ImportReport{Total: 1})
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	require.NoError(t, mw.WriteField("note", "ignored"))
+	part, err := mw.CreateFormFile("file", "customers.csv")
+	require.NoError(t, err)
+	part.Write([]byte("name,address,external_ref\nJane,1 Main St,crm-1\n"))
+	require.NoError(t, mw.Close())
+
+	rec := postImport(newImportHandler(svc, 100, 0), mw.FormDataContentType(), &buf)
+
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	svc.AssertExpectations(t)
+}
+
+func TestImportCustomersRejectsUpload(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		maxRows     int
+		maxBytes    int64
+		wantMessage string
+	}{
+		{"unsupported type", "application/json", `[]`, 10, 0, "unsupported upload type"},
+		{"missing column", "text/csv", "name,address\nJane,1 Main St\n", 10, 0, "CSV header is missing the"},
+		{"malformed csv", "text/csv", "name,address,external_ref\n\"Jane,1 Main St,crm-1\n", 10, 0, "malformed CSV"},
+		{"malformed ndjson", "application/x-ndjson", "{\"name\":\"Jane\"}\n{oops}\n", 10, 0, "line 2"},
+		{"unknown ndjson field", "application/x-ndjson", `{"name":"Jane","phone":"1"}`, 10, 0, "malformed NDJSON"},
+		{"too many rows", "text/csv", "name,address,external_ref\nA,a,1\nB,b,2\n", 1, 0, "exceeds 1 rows"},
+		{"too large", "application/x-ndjson", strings.Repeat(`{"name":"A","address":"a","externalRef":"1"}`+"\n", 10), 100, 64, "exceeds 64 bytes"},
+		{"empty", "text/csv", "name,address,external_ref\n", 10, 0, "no customer rows"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := new(MockImportService)
+			rec := postImport(newImportHandler(svc, tt.maxRows, tt.maxBytes), tt.contentType, strings.NewReader(tt.body))
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.Contains(t, rec.Body.String(), tt.wantMessage)
+			svc.AssertNotCalled(t, "ImportCustomers", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestImportCustomersInBackground(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	twoRows := "external_ref,name,address\ncrm-1,Jane,1 Main St\ncrm-2,Bob,2 Main St\n"
+	startRunner := func(t *testing.T) *jobs.Runner {
+		runner := jobs.NewRunner(jobs.NewMemoryStore(), jobs.Config{Workers: 1, QueueSize: 1, PollInterval: 5 * time.Millisecond}, nil, logger)
+		runner.Start(context.Background())
+		t.Cleanup(func() { runner.Stop(context.Background()) })
+		return runner
+	}
+
+	t.Run("queues uploads above the sync limit and serves the report from the job", func(t *testing.T) {
+		svc := new(MockImportService)
+		svc.On("ImportCustomers", mock.Anything, mock.Anything).Return(&customer.ImportReport{Total: 2, Created: 2}).Once()
+		runner := startRunner(t)
+		h := handler.NewCustomerImportHandler(svc, handler.NewBulkRunner(runner, 1, logger), 100, 0, logger)
+
+		rec := postImport(h, "text/csv", strings.NewReader(twoRows))
+
+		require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+		var job dto.JobResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&job))
+		assert.Equal(t, "/jobs/"+job.ID, rec.Header().Get("Location"))
+		assert.Equal(t, "customer-import", job.Kind)
+		assert.Equal(t, 2, job.Total)
+		assert.Empty(t, rec.Header().Get("Preference-Applied"))
+
+		router := chi.NewRouter()
+		router.Get("/jobs/{jobID}", handler.NewJobHandler(runner, logger).GetJob)
+		var polled struct {
+			Status string                     `json:"status"`
+			Result dto.CustomerImportResponse `json:"result"`
+		}
+		require.Eventually(t, func() bool {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/jobs/"+job.ID, nil))
+			return rec.Code == http.StatusOK && json.Unmarshal(rec.Body.Bytes(), &polled) == nil && polled.Status == "SUCCEEDED"
+		}, time.Second, 5*time.Millisecond)
+		assert.Equal(t, 2, polled.Result.Created)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("queues a small upload when the client prefers so", func(t *testing.T) {
+		svc := new(MockImportService)
+		svc.On("ImportCustomers", mock.Anything, mock.Anything).Return(&customer.ImportReport{Total: 2}).Maybe()
+		h := handler.NewCustomerImportHandler(svc, handler.NewBulkRunner(startRunner(t), 1000, logger), 100, 0, logger)
+
+		req := httptest.NewRequest(http.MethodPost, "/customers/import", strings.NewReader(twoRows))
+		req.Header.Set("Content-Type", "text/csv")
+		req.Header.Set("Prefer", "wait=5, respond-async")
+		rec := httptest.NewRecorder()
+		h.ImportCustomers(rec, req)
+
+		assert.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+		assert.Equal(t, "respond-async", rec.Header().Get("Preference-Applied"))
+	})
+
+	t.Run("imports within the request below the sync limit", func(t *testing.T) {
+		svc := new(MockImportService)
+		svc.On("ImportCustomers", mock.Anything, mock.Anything).Return(&customer.ImportReport{Total: 2, Created: 2}).Once()
+		h := handler.NewCustomerImportHandler(svc, handler.NewBulkRunner(startRunner(t), 2, logger), 100, 0, logger)
+
+		rec := postImport(h, "text/csv", strings.NewReader(twoRows))
+
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var resp dto.CustomerImportResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		assert.Equal(t, 2, resp.Created)
+	})
+
+	t.Run("refuses uploads while the job queue is full", func(t *testing.T) {
+		// A runner that is not started never takes jobs off its queue.
+		runner := jobs.NewRunner(jobs.NewMemoryStore(), jobs.Config{QueueSize: 1}, nil, logger)
+		h := handler.NewCustomerImportHandler(new(MockImportService), handler.NewBulkRunner(runner, 1, logger), 100, 0, logger)
+		require.Equal(t, http.StatusAccepted, postImport(h, "text/csv", strings.NewReader(twoRows)).Code)
+
+		rec := postImport(h, "text/csv", strings.NewReader(twoRows))
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Equal(t, "30", rec.Header().Get("Retry-After"))
+	})
+}
@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"billing-engine/internal/api/handler/dto"
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/domain/overview"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type CustomerOverviewHandler struct {
+	service   overview.Service
+	customers customer.CustomerService
+	logger    *slog.Logger
+}
+
+func NewCustomerOverviewHandler(s overview.Service, customers customer.CustomerService, l *slog.Logger) *CustomerOverviewHandler {
+	if s == nil {
+		panic("overview service cannot be nil")
+	}
+	if customers == nil {
+		panic("customer service cannot be nil")
+	}
+	if l == nil {
+		panic("logger cannot be nil")
+	}
+	return &CustomerOverviewHandler{
+		service:   s,
+		customers: customers,
+		logger:    l.With("component", "CustomerOverviewHandler"),
+	}
+}
+
+// GetOverview handles GET /customers/{customerID}/overview
+// @Summary Get a customer's overview for support
+// @Description Returns the customer, their loan if it is not paid off with what is owed and days past due, the latest payments, the open collections assignment with its actions and the latest notifications, read live from each source. Notifications come from notify-service; when it is not configured or cannot be reached they are empty and notificationsAvailable is false.
+// @Tags Customers
+// @Produce json
+// @Param customerID path string true "Customer ID or public UUID"
+// @Success 200 {object} dto.CustomerOverviewResponse "Customer overview"
+// @Failure 400 {object} dto.ErrorResponse "Invalid customer ID"
+// @Failure 404 {object} dto.ErrorResponse "Customer not found"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /customers/{customerID}/overview [get]
+// @Security BearerAuth
+func (h *CustomerOverviewHandler) GetOverview(w http.ResponseWriter, r *http.Request) {
+	customerID, err := resolveURLID(r.Context(), "customerID", chi.URLParam(r, "customerID"), h.customers.ResolveCustomerID)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	o, err := h.service.GetOverview(r.Context(), customerID)
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "Service failed to get customer overview", slog.Int64("customerID", customerID), slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, dto.NewCustomerOverviewResponse(o))
+}
@@ -0,0 +1,73 @@
+package handler_test
+
+import (
+	"billing-engine/internal/api/handler"
+	"billing-engine/internal/api/handler/dto"
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/domain/overview"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockOverviewService struct {
+	mock.Mock
+}
+
+func (m *MockOverviewService) GetOverview(ctx context.Context, customerID int64) (*overview.CustomerOverview, error) {
+	args := m.Called(ctx, customerID)
+	o, _ := args.Get(0).(*overview.CustomerOverview)
+	return o, args.Error(1)
+}
+
+func newCustomerOverviewHandler(svc overview.Service, customers *MockCustomerService) *handler.CustomerOverviewHandler {
+	return handler.NewCustomerOverviewHandler(svc, customers, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestCustomerOverviewHandlerGetOverview(t *testing.T) {
+	t.Run("returns the overview", func(t *testing.T) {
+		svc := new(MockOverviewService)
+		svc.On("GetOverview", mock.Anything, int64(7)).Return(&overview.CustomerOverview{
+			Customer:      &customer.Customer{CustomerID: 7, Name: "Jane Doe"},
+			Loans:         []overview.LoanOverview{{Loan: &loan.Loan{ID: 3, Status: loan.StatusActive, DaysPastDue: 4}, Outstanding: 440}},
+			Payments:      []loan.Payment{},
+			Notifications: []overview.Notification{},
+		}, nil).Once()
+
+		req := withURLParams(httptest.NewRequest(http.MethodGet, "/customers/7/overview", nil),
+			map[string]string{"customerID": "7"})
+		rr := httptest.NewRecorder()
+		newCustomerOverviewHandler(svc, new(MockCustomerService)).GetOverview(rr, req)
+
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var resp dto.CustomerOverviewResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+		assert.Equal(t, "7", resp.Customer.CustomerID)
+		require.Len(t, resp.Loans, 1)
+		assert.Equal(t, "440.00", resp.Loans[0].Outstanding)
+		assert.False(t, resp.NotificationsAvailable)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("reports an unknown customer", func(t *testing.T) {
+		svc := new(MockOverviewService)
+		svc.On("GetOverview", mock.Anything, int64(9)).Return(nil, apperrors.ErrNotFound).Once()
+
+		req := withURLParams(httptest.NewRequest(http.MethodGet, "/customers/9/overview", nil),
+			map[string]string{"customerID": "9"})
+		rr := httptest.NewRecorder()
+		newCustomerOverviewHandler(svc, new(MockCustomerService)).GetOverview(rr, req)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"billing-engine/internal/api/handler/dto"
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/domain/summary"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type CustomerSummaryHandler struct {
+	service   summary.Service
+	customers customer.CustomerService
+	logger    *slog.Logger
+}
+
+func NewCustomerSummaryHandler(s summary.Service, customers customer.CustomerService, l *slog.Logger) *CustomerSummaryHandler {
+	if s == nil {
+		panic("summary service cannot be nil")
+	}
+	if customers == nil {
+		panic("customer service cannot be nil")
+	}
+	if l == nil {
+		panic("logger cannot be nil")
+	}
+	return &CustomerSummaryHandler{
+		service:   s,
+		customers: customers,
+		logger:    l.With("component", "CustomerSummaryHandler"),
+	}
+}
+
+// GetSummary handles GET /customers/{customerID}/summary
+// @Summary Get a customer's loan summary
+// @Description Returns the customer together with the current loan's balance, installment progress and next due installment, read from a single projected row. Figures can trail a payment by a moment; days past due are as of the last nightly update.
+// @Tags Customers
+// @Produce json
+// @Param customerID path string true "Customer ID or public UUID"
+// @Success 200 {object} dto.CustomerSummaryResponse "Customer loan summary"
+// @Failure 400 {object} dto.ErrorResponse "Invalid customer ID"
+// @Failure 404 {object} dto.ErrorResponse "Customer not found"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /customers/{customerID}/summary [get]
+// @Security BearerAuth
+func (h *CustomerSummaryHandler) GetSummary(w http.ResponseWriter, r *http.Request) {
+	customerID, err := resolveURLID(r.Context(), "customerID", chi.URLParam(r, "customerID"), h.customers.ResolveCustomerID)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	s, err := h.service.GetSummary(r.Context(), customerID)
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "Service failed to get customer summary", slog.Int64("customerID", customerID), slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, dto.NewCustomerSummaryResponse(s))
+}
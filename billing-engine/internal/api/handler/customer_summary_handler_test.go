@@ -0,0 +1,105 @@
+package handler_test
+
+import (
+	"billing-engine/internal/api/handler"
+	"billing-engine/internal/api/handler/dto"
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/domain/summary"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockSummaryService struct {
+	mock.Mock
+}
+
+func (m *MockSummaryService) GetSummary(ctx context.Context, customerID int64) (*summary.CustomerLoanSummary, error) {
+	args := m.Called(ctx, customerID)
+	s, _ := args.Get(0).(*summary.CustomerLoanSummary)
+	return s, args.Error(1)
+}
+
+func (m *MockSummaryService) Refresh(ctx context.Context, customerID int64) error {
+	return m.Called(ctx, customerID).Error(0)
+}
+
+func (m *MockSummaryService) RefreshForLoan(ctx context.Context, loanID int64) error {
+	return m.Called(ctx, loanID).Error(0)
+}
+
+func (m *MockSummaryService) Rebuild(ctx context.Context) (int64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func newCustomerSummaryHandler(svc summary.Service, customers *MockCustomerService) *handler.CustomerSummaryHandler {
+	return handler.NewCustomerSummaryHandler(svc, customers, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestCustomerSummaryHandlerGetSummary(t *testing.T) {
+	t.Run("returns the summary of a customer addressed by UUID", func(t *testing.T) {
+		svc := new(MockSummaryService)
+		customers := new(MockCustomerService)
+		publicID := uuid.New()
+		loanID := int64(3)
+		status := loan.StatusActive
+		due := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+		customers.On("ResolveCustomerID", mock.Anything, publicID).Return(int64(7), nil).Once()
+		svc.On("GetSummary", mock.Anything, int64(7)).Return(&summary.CustomerLoanSummary{
+			CustomerID: 7, CustomerPublicID: publicID, Name: "Jane Doe", Active: true,
+			LoanID: &loanID, LoanStatus: &status, PrincipalAmount: 5000000, TotalLoanAmount: 5500000,
+			TotalPaid: 220000, Outstanding: 5280000, InstallmentsPaid: 2, InstallmentsTotal: 50,
+			NextDueDate: &due, NextDueAmount: 110000,
+		}, nil).Once()
+
+		req := withURLParams(httptest.NewRequest(http.MethodGet, "/customers/"+publicID.String()+"/summary", nil),
+			map[string]string{"customerID": publicID.String()})
+		rr := httptest.NewRecorder()
+		newCustomerSummaryHandler(svc, customers).GetSummary(rr, req)
+
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var resp dto.CustomerSummaryResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+		assert.Equal(t, "7", resp.CustomerID)
+		require.NotNil(t, resp.Loan)
+		assert.Equal(t, "5280000.00", resp.Loan.Outstanding)
+		assert.Equal(t, "2025-03-10", *resp.Loan.NextDueDate)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("reports an unknown customer", func(t *testing.T) {
+		svc := new(MockSummaryService)
+		svc.On("GetSummary", mock.Anything, int64(9)).Return(nil, apperrors.ErrNotFound).Once()
+
+		req := withURLParams(httptest.NewRequest(http.MethodGet, "/customers/9/summary", nil),
+			map[string]string{"customerID": "9"})
+		rr := httptest.NewRecorder()
+		newCustomerSummaryHandler(svc, new(MockCustomerService)).GetSummary(rr, req)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("rejects a malformed customer ID", func(t *testing.T) {
+		svc := new(MockSummaryService)
+
+		req := withURLParams(httptest.NewRequest(http.MethodGet, "/customers/abc/summary", nil),
+			map[string]string{"customerID": "abc"})
+		rr := httptest.NewRecorder()
+		newCustomerSummaryHandler(svc, new(MockCustomerService)).GetSummary(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		svc.AssertNotCalled(t, "GetSummary", mock.Anything, mock.Anything)
+	})
+}
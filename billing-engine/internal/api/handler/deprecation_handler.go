@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"billing-engine/internal/api/handler/dto"
+	"billing-engine/internal/infrastructure/monitoring"
+	"billing-engine/internal/pkg/apperrors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultDeprecatedUsage = 50
+	maxDeprecatedUsage     = 1000
+)
+
+// DeprecationHandler shows who still calls the deprecated routes, so that
+// they can be told before the routes are removed.
+type DeprecationHandler struct {
+	tracker *monitoring.DeprecationTracker
+	logger  *slog.Logger
+}
+
+func NewDeprecationHandler(t *monitoring.DeprecationTracker, l *slog.Logger) *DeprecationHandler {
+	if t == nil {
+		panic("deprecation tracker cannot be nil")
+	}
+	if l == nil {
+		panic("logger cannot be nil")
+	}
+	return &DeprecationHandler{tracker: t, logger: l.With("component", "DeprecationHandler")}
+}
+
+// ListUsage handles GET /admin/deprecations
+// @Summary List the clients of deprecated routes
+// @Description Lists the clients that called a deprecated route, busiest first, per route: the unprefixed routes kept for clients from before /v1 and those in server.api.deprecatedRoutes. A client is the subject of its token, or its IP when it sent none. The counts are kept in memory and start over when the instance restarts.
+// @Tags Monitoring
+// @Produce json
+// @Param limit query int false "Maximum number of entries, default 50, at most 1000"
+// @Success 200 {array} dto.DeprecatedUsageResponse
+// @Failure 400 {object} dto.ErrorResponse "Invalid limit"
+// @Failure 403 {object} dto.ErrorResponse "Token lacks the admin scope"
+// @Router /admin/deprecations [get]
+// @Security BearerAuth
+func (h *DeprecationHandler) ListUsage(w http.ResponseWriter, r *http.Request) {
+	limit := defaultDeprecatedUsage
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxDeprecatedUsage {
+			respondError(w, fmt.Errorf("%w: limit must be between 1 and %d", apperrors.ErrInvalidArgument, maxDeprecatedUsage))
+			return
+		}
+		limit = n
+	}
+	respondJSON(w, http.StatusOK, dto.NewDeprecatedUsageListResponse(h.tracker.Usage(limit)))
+}
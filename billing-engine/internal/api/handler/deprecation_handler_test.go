@@ -0,0 +1,39 @@
+package handler_test
+
+import (
+	"billing-engine/internal/api/handler"
+	"billing-engine/internal/api/handler/dto"
+	"billing-engine/internal/infrastructure/monitoring"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeprecationHandlerListUsage(t *testing.T) {
+	tracker := monitoring.NewDeprecationTracker()
+	at := time.Date(2025, 4, 2, 9, 0, 0, 0, time.UTC)
+	tracker.Record(http.MethodGet, "/loans/{loanID}", "collections-batch", at)
+	tracker.Record(http.MethodGet, "/loans/{loanID}", "collections-batch", at.Add(time.Hour))
+	tracker.Record(http.MethodPost, "/loans/{loanID}/payments", "198.51.100.4", at)
+	h := handler.NewDeprecationHandler(tracker, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	rec := httptest.NewRecorder()
+	h.ListUsage(rec, httptest.NewRequest(http.MethodGet, "/admin/deprecations?limit=1", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp []dto.DeprecatedUsageResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.Equal(t, []dto.DeprecatedUsageResponse{{Method: http.MethodGet, Route: "/loans/{loanID}", Client: "collections-batch",
+		Requests: 2, FirstSeen: at, LastSeen: at.Add(time.Hour)}}, resp)
+
+	rec = httptest.NewRecorder()
+	h.ListUsage(rec, httptest.NewRequest(http.MethodGet, "/admin/deprecations?limit=1001", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
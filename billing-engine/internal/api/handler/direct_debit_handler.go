@@ -0,0 +1,210 @@
+package handler
+
+import (
+	"billing-engine/internal/api/handler/dto"
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/domain/directdebit"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const jobKindDirectDebitResults = "direct-debit-results"
+
+type DirectDebitHandler struct {
+	service        directdebit.Service
+	customers      customer.CustomerService
+	bulk           *BulkRunner
+	maxResultBytes int64
+	maxResultRows  int
+	logger         *slog.Logger
+}
+
+// NewDirectDebitHandler caps result files at maxResultBytes and
+// maxResultRows, zero meaning no cap. Large files are processed as a job on
+// bulk; a nil bulk processes every file within the request.
+func NewDirectDebitHandler(s directdebit.Service, customers customer.CustomerService, bulk *BulkRunner, maxResultBytes int64, maxResultRows int, l *slog.Logger) *DirectDebitHandler {
+	if s == nil {
+		panic("direct-debit service cannot be nil")
+	}
+	if customers == nil {
+		panic("customer service cannot be nil")
+	}
+	if l == nil {
+		panic("logger cannot be nil")
+	}
+	h := &DirectDebitHandler{
+		service:        s,
+		customers:      customers,
+		bulk:           bulk,
+		maxResultBytes: maxResultBytes,
+		maxResultRows:  maxResultRows,
+		logger:         l.With("component", "DirectDebitHandler"),
+	}
+	registerBulkTask(bulk, jobKindDirectDebitResults, h.processResults)
+	return h
+}
+
+func (h *DirectDebitHandler) customerFromURL(r *http.Request) (int64, error) {
+	return resolveURLID(r.Context(), "customerID", chi.URLParam(r, "customerID"), h.customers.ResolveCustomerID)
+}
+
+// CreateMandate handles POST /customers/{customerID}/mandates
+// @Summary Register a direct-debit mandate
+// @Description Records the customer's authorisation to collect installments from a bank account. A customer holds at most one active mandate; cancel it before registering another.
+// @Tags Direct Debit
+// @Accept json
+// @Produce json
+// @Param customerID path string true "Customer ID or public UUID"
+// @Param request body dto.CreateMandateRequest true "Mandate"
+// @Success 201 {object} dto.MandateResponse "Mandate registered"
+// @Failure 400 {object} dto.ErrorResponse "Invalid request payload"
+// @Failure 404 {object} dto.ErrorResponse "Customer not found"
+// @Failure 409 {object} dto.ErrorResponse "Customer already has an active mandate, or the reference is in use"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /customers/{customerID}/mandates [post]
+// @Security BearerAuth
+func (h *DirectDebitHandler) CreateMandate(w http.ResponseWriter, r *http.Request) {
+	customerID, err := h.customerFromURL(r)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	var req dto.CreateMandateRequest
+	if err := decodeJSON(r, &req); err != nil {
+		h.logger.WarnContext(r.Context(), "Failed to decode request body", slog.Any("error", err))
+		respondError(w, fmt.Errorf("%w: %w", apperrors.ErrInvalidArgument, err))
+		return
+	}
+	if err := req.Validate(); err != nil {
+		respondError(w, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err))
+		return
+	}
+
+	created, err := h.service.CreateMandate(r.Context(), req.Mandate(customerID))
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "Service failed to create mandate", slog.Int64("customerID", customerID), slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, dto.NewMandateResponse(created))
+}
+
+// ListMandates handles GET /customers/{customerID}/mandates
+// @Summary List direct-debit mandates
+// @Description Lists the active and cancelled mandates of a customer, newest first.
+// @Tags Direct Debit
+// @Produce json
+// @Param customerID path string true "Customer ID or public UUID"
+// @Success 200 {array} dto.MandateResponse "Mandates"
+// @Failure 404 {object} dto.ErrorResponse "Customer not found"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /customers/{customerID}/mandates [get]
+// @Security BearerAuth
+func (h *DirectDebitHandler) ListMandates(w http.ResponseWriter, r *http.Request) {
+	customerID, err := h.customerFromURL(r)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	mandates, err := h.service.ListMandates(r.Context(), customerID)
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "Service failed to list mandates", slog.Int64("customerID", customerID), slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, dto.NewMandateListResponse(mandates))
+}
+
+// CancelMandate handles DELETE /customers/{customerID}/mandates/{mandateID}
+// @Summary Cancel a direct-debit mandate
+// @Description Stops future collections. Instructions already sent to the bank are still settled by their result file.
+// @Tags Direct Debit
+// @Param customerID path string true "Customer ID or public UUID"
+// @Param mandateID path int true "Mandate ID"
+// @Success 204 "Mandate cancelled"
+// @Failure 404 {object} dto.ErrorResponse "No active mandate with this ID for the customer"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /customers/{customerID}/mandates/{mandateID} [delete]
+// @Security BearerAuth
+func (h *DirectDebitHandler) CancelMandate(w http.ResponseWriter, r *http.Request) {
+	customerID, err := h.customerFromURL(r)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	mandateID, err := int64URLParam(r, "mandateID")
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	if err := h.service.CancelMandate(r.Context(), customerID, mandateID); err != nil {
+		h.logger.WarnContext(r.Context(), "Service failed to cancel mandate", slog.Int64("mandateID", mandateID), slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ProcessResults handles POST /direct-debit/results
+// @Summary Process a bank result file
+// @Description Accepts a CSV result file (text/csv) with a header row naming the end_to_end_id, status and optional reason columns. Status is COLLECTED or FAILED, or the ISO 20022 codes ACSC and RJCT. Collected instructions are posted as loan payments and failed ones are retried by the next weekly run. Processing a file twice posts nothing the second time. Files of more than bulk.syncMaxRows rows, or sent with `Prefer: respond-async`, are processed in the background: the answer is 202 with the job to poll at the Location header.
+// @Tags Direct Debit
+// @Accept text/csv
+// @Produce json
+// @Success 200 {object} dto.DirectDebitResultsResponse "Per-row processing report"
+// @Success 202 {object} dto.JobResponse "Processing continues in the background"
+// @Failure 400 {object} dto.ErrorResponse "Unsupported, malformed or oversized upload"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Failure 503 {object} dto.ErrorResponse "Too many bulk jobs in progress, retry after the Retry-After delay"
+// @Router /direct-debit/results [post]
+// @Security BearerAuth
+func (h *DirectDebitHandler) ProcessResults(w http.ResponseWriter, r *http.Request) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || (mediaType != "text/csv" && mediaType != "application/csv") {
+		respondError(w, fmt.Errorf("%w: unsupported upload type, use text/csv", apperrors.ErrInvalidArgument))
+		return
+	}
+	if h.maxResultBytes > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, h.maxResultBytes)
+	}
+	defer r.Body.Close()
+
+	rows, err := directdebit.ReadResults(r.Body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			err = fmt.Errorf("%w: upload exceeds %d bytes", apperrors.ErrInvalidArgument, maxBytesErr.Limit)
+		}
+		h.logger.WarnContext(r.Context(), "Rejected direct-debit result file", slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	if len(rows) == 0 {
+		respondError(w, fmt.Errorf("%w: result file contains no rows", apperrors.ErrInvalidArgument))
+		return
+	}
+	if h.maxResultRows > 0 && len(rows) > h.maxResultRows {
+		respondError(w, fmt.Errorf("%w: result file exceeds %d rows", apperrors.ErrInvalidArgument, h.maxResultRows))
+		return
+	}
+
+	respondBulk(h.bulk, w, r, jobKindDirectDebitResults, len(rows), rows, h.processResults)
+}
+
+func (h *DirectDebitHandler) processResults(ctx context.Context, rows []directdebit.ResultRow, progress func(int)) (any, error) {
+	report := h.service.ProcessResults(ctx, rows, progress)
+	h.logger.InfoContext(ctx, "Direct-debit result file processed",
+		slog.Int("total", report.Total), slog.Int("collected", report.Collected),
+		slog.Int("failed", report.Failed), slog.Int("unapplied", report.Unapplied))
+	return dto.NewDirectDebitResultsResponse(report), nil
+}
@@ -0,0 +1,198 @@
+package handler_test
+
+import (
+	"billing-engine/internal/api/handler"
+	"billing-engine/internal/api/handler/dto"
+	"billing-engine/internal/domain/directdebit"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockDirectDebitService struct {
+	mock.Mock
+}
+
+func (m *MockDirectDebitService) CreateMandate(ctx context.Context, mandate *directdebit.Mandate) (*directdebit.Mandate, error) {
+	args := m.Called(ctx, mandate)
+	created, _ := args.Get(0).(*directdebit.Mandate)
+	return created, args.Error(1)
+}
+
+func (m *MockDirectDebitService) ListMandates(ctx context.Context, customerID int64) ([]directdebit.Mandate, error) {
+	args := m.Called(ctx, customerID)
+	mandates, _ := args.Get(0).([]directdebit.Mandate)
+	return mandates, args.Error(1)
+}
+
+func (m *MockDirectDebitService) CancelMandate(ctx context.Context, customerID, mandateID int64) error {
+	return m.Called(ctx, customerID, mandateID).Error(0)
+}
+
+func (m *MockDirectDebitService) GenerateInstructions(ctx context.Context, asOf time.Time) (int, error) {
+	args := m.Called(ctx, asOf)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockDirectDebitService) ExportBatch(ctx context.Context, w io.Writer) (*directdebit.Batch, error) {
+	args := m.Called(ctx, w)
+	batch, _ := args.Get(0).(*directdebit.Batch)
+	return batch, args.Error(1)
+}
+
+func (m *MockDirectDebitService) ProcessResults(ctx context.Context, rows []directdebit.ResultRow, progress func(int)) *directdebit.ResultReport {
+	return m.Called(ctx, rows).Get(0).(*directdebit.ResultReport)
+}
+
+func newDirectDebitHandler(svc directdebit.Service, customers *MockCustomerService, maxResultBytes int64) *handler.DirectDebitHandler {
+	return handler.NewDirectDebitHandler(svc, customers, nil, maxResultBytes, 0, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestDirectDebitHandlerCreateMandate(t *testing.T) {
+	body := `{"accountHolder":"Jane Doe","accountNumber":"9876543210","bankCode":"BANKIDJB","signedAt":"2025-01-02"}`
+
+	t.Run("registers the mandate for a customer addressed by UUID", func(t *testing.T) {
+		svc := new(MockDirectDebitService)
+		customers := new(MockCustomerService)
+		publicID := uuid.New()
+		customers.On("ResolveCustomerID", mock.Anything, publicID).Return(int64(7), nil).Once()
+		svc.On("CreateMandate", mock.Anything, mock.MatchedBy(func(m *directdebit.Mandate) bool {
+			return m.CustomerID == 7 && m.AccountNumber == "9876543210" && m.SignedAt.Equal(time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC))
+		})).Return(&directdebit.Mandate{
+			ID: 3, CustomerID: 7, Reference: "MNDT-ABC", AccountHolder: "Jane Doe", AccountNumber: "9876543210",
+			BankCode: "BANKIDJB", Status: directdebit.MandateActive, SignedAt: time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC),
+		}, nil).Once()
+
+		req := withURLParams(httptest.NewRequest(http.MethodPost, "/customers/"+publicID.String()+"/mandates", strings.NewReader(body)),
+			map[string]string{"customerID": publicID.String()})
+		rr := httptest.NewRecorder()
+		newDirectDebitHandler(svc, customers, 0).CreateMandate(rr, req)
+
+		require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+		var resp dto.MandateResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+		assert.Equal(t, "3", resp.ID)
+		assert.Equal(t, "MNDT-ABC", resp.Reference)
+		assert.Equal(t, "2025-01-02", resp.SignedAt)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("rejects a second active mandate", func(t *testing.T) {
+		svc := new(MockDirectDebitService)
+		svc.On("CreateMandate", mock.Anything, mock.Anything).Return(nil, apperrors.ErrConflict).Once()
+
+		req := withURLParams(httptest.NewRequest(http.MethodPost, "/customers/7/mandates", strings.NewReader(body)),
+			map[string]string{"customerID": "7"})
+		rr := httptest.NewRecorder()
+		newDirectDebitHandler(svc, new(MockCustomerService), 0).CreateMandate(rr, req)
+
+		assert.Equal(t, http.StatusConflict, rr.Code)
+	})
+
+	t.Run("rejects a missing signature date", func(t *testing.T) {
+		svc := new(MockDirectDebitService)
+
+		req := withURLParams(httptest.NewRequest(http.MethodPost, "/customers/7/mandates",
+			strings.NewReader(`{"accountHolder":"Jane Doe","accountNumber":"9876543210","bankCode":"BANKIDJB"}`)),
+			map[string]string{"customerID": "7"})
+		rr := httptest.NewRecorder()
+		newDirectDebitHandler(svc, new(MockCustomerService), 0).CreateMandate(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		svc.AssertNotCalled(t, "CreateMandate", mock.Anything, mock.Anything)
+	})
+}
+
+func TestDirectDebitHandlerCancelMandateNotFound(t *testing.T) {
+	svc := new(MockDirectDebitService)
+	svc.On("CancelMandate", mock.Anything, int64(7), int64(3)).Return(apperrors.ErrNotFound).Once()
+
+	req := withURLParams(httptest.NewRequest(http.MethodDelete, "/customers/7/mandates/3", nil),
+		map[string]string{"customerID": "7", "mandateID": "3"})
+	rr := httptest.NewRecorder()
+	newDirectDebitHandler(svc, new(MockCustomerService), 0).CancelMandate(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	svc.AssertExpectations(t)
+}
+
+func TestDirectDebitHandlerProcessResults(t *testing.T) {
+	t.Run("reports the outcome of every row", func(t *testing.T) {
+		svc := new(MockDirectDebitService)
+		svc.On("ProcessResults", mock.Anything, []directdebit.ResultRow{
+			{Line: 2, EndToEndID: "E2E1", Collected: true},
+			{Line: 3, EndToEndID: "E2E2", Reason: "AM04"},
+		}).Return(&directdebit.ResultReport{
+			Total: 2, Collected: 1, Failed: 1,
+			Results: []directdebit.ResultOutcome{
+				{Line: 2, EndToEndID: "E2E1", Status: directdebit.ResultStatusCollected, LoanID: 5},
+				{Line: 3, EndToEndID: "E2E2", Status: directdebit.ResultStatusFailed, LoanID: 5},
+			},
+		}).Once()
+
+		req := httptest.NewRequest(http.MethodPost, "/direct-debit/results",
+			strings.NewReader("end_to_end_id,status,reason\nE2E1,COLLECTED,\nE2E2,RJCT,AM04\n"))
+		req.Header.Set("Content-Type", "text/csv")
+		rr := httptest.NewRecorder()
+		newDirectDebitHandler(svc, new(MockCustomerService), 1<<20).ProcessResults(rr, req)
+
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var resp dto.DirectDebitResultsResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+		assert.Equal(t, 1, resp.Collected)
+		assert.Equal(t, 1, resp.Failed)
+		require.Len(t, resp.Results, 2)
+		svc.AssertExpectations(t)
+	})
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		maxBytes    int64
+		wantError   string
+	}{
+		{"wrong content type", "application/json", `{}`, 0, "use text/csv"},
+		{"unknown status", "text/csv", "end_to_end_id,status\nE2E1,MAYBE\n", 0, "unknown status"},
+		{"header only", "text/csv", "end_to_end_id,status\n", 0, "no rows"},
+		{"oversized upload", "text/csv", "end_to_end_id,status\n" + strings.Repeat("E2E1,COLLECTED\n", 10), 32, "exceeds 32 bytes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := new(MockDirectDebitService)
+			req := httptest.NewRequest(http.MethodPost, "/direct-debit/results", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			rr := httptest.NewRecorder()
+			newDirectDebitHandler(svc, new(MockCustomerService), tt.maxBytes).ProcessResults(rr, req)
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+			assert.Contains(t, rr.Body.String(), tt.wantError)
+			svc.AssertNotCalled(t, "ProcessResults", mock.Anything, mock.Anything)
+		})
+	}
+
+	t.Run("too many rows", func(t *testing.T) {
+		svc := new(MockDirectDebitService)
+		req := httptest.NewRequest(http.MethodPost, "/direct-debit/results",
+			strings.NewReader("end_to_end_id,status\n"+strings.Repeat("E2E1,COLLECTED\n", 3)))
+		req.Header.Set("Content-Type", "text/csv")
+		rr := httptest.NewRecorder()
+		handler.NewDirectDebitHandler(svc, new(MockCustomerService), nil, 0, 2, slog.New(slog.NewTextHandler(io.Discard, nil))).ProcessResults(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Contains(t, rr.Body.String(), "exceeds 2 rows")
+		svc.AssertNotCalled(t, "ProcessResults", mock.Anything, mock.Anything)
+	})
+}
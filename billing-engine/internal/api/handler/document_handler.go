@@ -0,0 +1,237 @@
+package handler
+
+import (
+	"billing-engine/internal/api/handler/dto"
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/domain/document"
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/pkg/apperrors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// DocumentHandler serves the documents routes mounted under both
+// /loans/{loanID} and /customers/{customerID}; the URL parameter present
+// decides the subject.
+type DocumentHandler struct {
+	service   document.Service
+	loans     loan.LoanService
+	customers customer.CustomerService
+	logger    *slog.Logger
+}
+
+func NewDocumentHandler(s document.Service, loans loan.LoanService, customers customer.CustomerService, l *slog.Logger) *DocumentHandler {
+	if s == nil {
+		panic("document service cannot be nil")
+	}
+	if loans == nil || customers == nil {
+		panic("loan and customer services cannot be nil")
+	}
+	if l == nil {
+		panic("logger cannot be nil")
+	}
+	return &DocumentHandler{service: s, loans: loans, customers: customers, logger: l.With("component", "DocumentHandler")}
+}
+
+func (h *DocumentHandler) subjectFromURL(r *http.Request) (document.Subject, error) {
+	if raw := chi.URLParam(r, "loanID"); raw != "" {
+		id, err := resolveURLID(r.Context(), "loanID", raw, h.loans.ResolveLoanID)
+		return document.LoanSubject(id), err
+	}
+	if raw := chi.URLParam(r, "customerID"); raw != "" {
+		id, err := resolveURLID(r.Context(), "customerID", raw, h.customers.ResolveCustomerID)
+		return document.Subject{Type: document.SubjectCustomer, ID: id}, err
+	}
+	return document.Subject{}, fmt.Errorf("%w: loanID or customerID not found in URL path", apperrors.ErrInvalidArgument)
+}
+
+// ListDocuments handles GET /loans/{loanID}/documents and GET /customers/{customerID}/documents
+// @Summary List documents
+// @Description Lists the documents of a loan or customer, newest first, pending uploads included.
+// @Tags Documents
+// @Produce json
+// @Param loanID path string true "Loan ID or public UUID"
+// @Success 200 {array} dto.DocumentResponse "Documents"
+// @Failure 404 {object} dto.ErrorResponse "Loan or customer not found"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /loans/{loanID}/documents [get]
+// @Security BearerAuth
+func (h *DocumentHandler) ListDocuments(w http.ResponseWriter, r *http.Request) {
+	subject, err := h.subjectFromURL(r)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	documents, err := h.service.List(r.Context(), subject)
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "Service failed to list documents", slog.String("subject", subject.String()), slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, dto.NewDocumentListResponse(documents))
+}
+
+// RequestUpload handles POST /loans/{loanID}/documents and POST /customers/{customerID}/documents
+// @Summary Start a document upload
+// @Description Records a pending document and returns a pre-signed link the file is uploaded to with a PUT. Contracts are uploaded to loans and ID copies to customers.
+// @Tags Documents
+// @Accept json
+// @Produce json
+// @Param loanID path string true "Loan ID or public UUID"
+// @Param request body dto.CreateDocumentRequest true "File to upload"
+// @Success 201 {object} dto.DocumentUploadResponse "Pending document and its upload link"
+// @Failure 400 {object} dto.ErrorResponse "Invalid request payload or type not allowed here"
+// @Failure 404 {object} dto.ErrorResponse "Loan or customer not found"
+// @Failure 503 {object} dto.ErrorResponse "Object storage is not configured"
+// @Router /loans/{loanID}/documents [post]
+// @Security BearerAuth
+func (h *DocumentHandler) RequestUpload(w http.ResponseWriter, r *http.Request) {
+	subject, err := h.subjectFromURL(r)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	var req dto.CreateDocumentRequest
+	if err := decodeJSON(r, &req); err != nil {
+		h.logger.WarnContext(r.Context(), "Failed to decode request body", slog.Any("error", err))
+		respondError(w, fmt.Errorf("%w: %w", apperrors.ErrInvalidArgument, err))
+		return
+	}
+	if err := req.Validate(); err != nil {
+		respondError(w, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err))
+		return
+	}
+	docType, _ := document.ParseType(req.Type)
+
+	pending, err := h.service.RequestUpload(r.Context(), subject, document.Upload{
+		Type:        docType,
+		FileName:    req.FileName,
+		ContentType: strings.TrimSpace(req.ContentType),
+		SizeBytes:   req.SizeBytes,
+		UploadedBy:  actorFromContext(r.Context()),
+	})
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "Service failed to start document upload", slog.String("subject", subject.String()), slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, dto.NewDocumentUploadResponse(pending))
+}
+
+// ConfirmUpload handles POST /loans/{loanID}/documents/{documentID}/confirm and POST /customers/{customerID}/documents/{documentID}/confirm
+// @Summary Confirm a document upload
+// @Description Checks that the file of a pending document is stored with the announced size and makes the document available. Confirming an available document returns it unchanged.
+// @Tags Documents
+// @Produce json
+// @Param loanID path string true "Loan ID or public UUID"
+// @Param documentID path int true "Document ID"
+// @Success 200 {object} dto.DocumentResponse "Available document"
+// @Failure 404 {object} dto.ErrorResponse "Document not found on this loan or customer"
+// @Failure 409 {object} dto.ErrorResponse "File not uploaded yet, or not of the announced size"
+// @Failure 503 {object} dto.ErrorResponse "Object storage is not configured"
+// @Router /loans/{loanID}/documents/{documentID}/confirm [post]
+// @Security BearerAuth
+func (h *DocumentHandler) ConfirmUpload(w http.ResponseWriter, r *http.Request) {
+	subject, err := h.subjectFromURL(r)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	documentID, err := int64URLParam(r, "documentID")
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	d, err := h.service.ConfirmUpload(r.Context(), subject, documentID)
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "Service failed to confirm document upload", slog.Int64("documentID", documentID), slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, dto.NewDocumentResponse(d))
+}
+
+// DownloadDocument handles GET /loans/{loanID}/documents/{documentID} and GET /customers/{customerID}/documents/{documentID}
+// @Summary Download a document
+// @Description Returns the content of the document as an attachment.
+// @Tags Documents
+// @Produce application/pdf
+// @Param loanID path string true "Loan ID or public UUID"
+// @Param documentID path int true "Document ID"
+// @Success 200 {file} file "Document content"
+// @Failure 404 {object} dto.ErrorResponse "Document not found on this loan or customer"
+// @Failure 409 {object} dto.ErrorResponse "Upload not confirmed yet"
+// @Failure 503 {object} dto.ErrorResponse "Object storage is not configured"
+// @Router /loans/{loanID}/documents/{documentID} [get]
+// @Security BearerAuth
+func (h *DocumentHandler) DownloadDocument(w http.ResponseWriter, r *http.Request) {
+	subject, err := h.subjectFromURL(r)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	documentID, err := int64URLParam(r, "documentID")
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	d, content, err := h.service.Open(r.Context(), subject, documentID)
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "Service failed to open document", slog.Int64("documentID", documentID), slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", d.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": d.FileName}))
+	w.Header().Set("Content-Length", strconv.FormatInt(d.SizeBytes, 10))
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, content); err != nil {
+		// The status is already sent; all that is left is to log it.
+		h.logger.WarnContext(r.Context(), "Failed to send document content", slog.Int64("documentID", documentID), slog.Any("error", err))
+	}
+}
+
+// DeleteDocument handles DELETE /loans/{loanID}/documents/{documentID} and DELETE /customers/{customerID}/documents/{documentID}
+// @Summary Delete a document
+// @Description Deletes an uploaded document and its stored object. Payoff certificates cannot be deleted.
+// @Tags Documents
+// @Param loanID path string true "Loan ID or public UUID"
+// @Param documentID path int true "Document ID"
+// @Success 204 "Document deleted"
+// @Failure 404 {object} dto.ErrorResponse "Document not found on this loan or customer"
+// @Failure 409 {object} dto.ErrorResponse "Document is a payoff certificate"
+// @Failure 503 {object} dto.ErrorResponse "Object storage is not configured"
+// @Router /loans/{loanID}/documents/{documentID} [delete]
+// @Security BearerAuth
+func (h *DocumentHandler) DeleteDocument(w http.ResponseWriter, r *http.Request) {
+	subject, err := h.subjectFromURL(r)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	documentID, err := int64URLParam(r, "documentID")
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	if err := h.service.Delete(r.Context(), subject, documentID); err != nil {
+		h.logger.WarnContext(r.Context(), "Service failed to delete document", slog.Int64("documentID", documentID), slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
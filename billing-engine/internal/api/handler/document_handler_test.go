@@ -0,0 +1,290 @@
+package handler_test
+
+import (
+	"billing-engine/internal/api/handler"
+	"billing-engine/internal/api/handler/dto"
+	"billing-engine/internal/domain/document"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockDocumentService struct {
+	mock.Mock
+}
+
+func (m *MockDocumentService) IssuePayoffCertificates(ctx context.Context) (*document.IssueReport, error) {
+	args := m.Called(ctx)
+	r, _ := args.Get(0).(*document.IssueReport)
+	return r, args.Error(1)
+}
+
+func (m *MockDocumentService) IssueLoanAgreement(ctx context.Context, agreement document.LoanAgreement) (*document.Document, []byte, error) {
+	args := m.Called(ctx, agreement)
+	d, _ := args.Get(0).(*document.Document)
+	content, _ := args.Get(1).([]byte)
+	return d, content, args.Error(2)
+}
+
+func (m *MockDocumentService) RequestUpload(ctx context.Context, subject document.Subject, upload document.Upload) (*document.PendingUpload, error) {
+	args := m.Called(ctx, subject, upload)
+	p, _ := args.Get(0).(*document.PendingUpload)
+	return p, args.Error(1)
+}
+
+func (m *MockDocumentService) ConfirmUpload(ctx context.Context, subject document.Subject, documentID int64) (*document.Document, error) {
+	args := m.Called(ctx, subject, documentID)
+	d, _ := args.Get(0).(*document.Document)
+	return d, args.Error(1)
+}
+
+func (m *MockDocumentService) List(ctx context.Context, subject document.Subject) ([]document.Document, error) {
+	args := m.Called(ctx, subject)
+	documents, _ := args.Get(0).([]document.Document)
+	return documents, args.Error(1)
+}
+
+func (m *MockDocumentService) Open(ctx context.Context, subject document.Subject, documentID int64) (*document.Document, io.ReadCloser, error) {
+	args := m.Called(ctx, subject, documentID)
+	d, _ := args.Get(0).(*document.Document)
+	content, _ := args.Get(1).(io.ReadCloser)
+	return d, content, args.Error(2)
+}
+
+func (m *MockDocumentService) Delete(ctx context.Context, subject document.Subject, documentID int64) error {
+	return m.Called(ctx, subject, documentID).Error(0)
+}
+
+func (m *MockDocumentService) ApplyRetention(ctx context.Context) (*document.RetentionReport, error) {
+	args := m.Called(ctx)
+	r, _ := args.Get(0).(*document.RetentionReport)
+	return r, args.Error(1)
+}
+
+func newDocumentHandler(svc document.Service, customers *MockCustomerService) *handler.DocumentHandler {
+	return handler.NewDocumentHandler(svc, stubNoteLoanService{}, customers, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestDocumentHandlerListDocuments(t *testing.T) {
+	t.Run("lists the loan's documents", func(t *testing.T) {
+		svc := new(MockDocumentService)
+		svc.On("List", mock.Anything, document.LoanSubject(42)).Return([]document.Document{{
+			ID: 5, Subject: document.LoanSubject(42), Type: document.TypePayoffCertificate, Status: document.StatusAvailable, FileName: "payoff-certificate-42.pdf",
+			ContentType: document.ContentTypePDF, SizeBytes: 1024, StorageKey: "loans/42/documents/key.pdf", CreatedAt: time.Now(),
+		}}, nil).Once()
+
+		req := withURLParams(httptest.NewRequest(http.MethodGet, "/loans/42/documents", nil), map[string]string{"loanID": "42"})
+		rec := httptest.NewRecorder()
+		newDocumentHandler(svc, new(MockCustomerService)).ListDocuments(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var resp []dto.DocumentResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		require.Len(t, resp, 1)
+		assert.Equal(t, "5", resp[0].ID)
+		assert.Equal(t, "PAYOFF_CERTIFICATE", resp[0].Type)
+		assert.Equal(t, "loan", resp[0].SubjectType)
+		assert.Equal(t, "42", resp[0].SubjectID)
+		assert.Equal(t, "AVAILABLE", resp[0].Status)
+		assert.NotContains(t, rec.Body.String(), "loans/42/documents/key.pdf", "the storage key is not exposed")
+	})
+
+	t.Run("reports an unknown loan", func(t *testing.T) {
+		svc := new(MockDocumentService)
+		svc.On("List", mock.Anything, document.LoanSubject(42)).Return(nil, fmt.Errorf("%w: loan 42", apperrors.ErrNotFound)).Once()
+
+		req := withURLParams(httptest.NewRequest(http.MethodGet, "/loans/42/documents", nil), map[string]string{"loanID": "42"})
+		rec := httptest.NewRecorder()
+		newDocumentHandler(svc, new(MockCustomerService)).ListDocuments(rec, req)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("lists a customer's documents by public ID", func(t *testing.T) {
+		svc, customers := new(MockDocumentService), new(MockCustomerService)
+		publicID := uuid.New()
+		customers.On("ResolveCustomerID", mock.Anything, publicID).Return(int64(7), nil).Once()
+		svc.On("List", mock.Anything, document.Subject{Type: document.SubjectCustomer, ID: 7}).Return([]document.Document{}, nil).Once()
+
+		req := withURLParams(httptest.NewRequest(http.MethodGet, "/customers/"+publicID.String()+"/documents", nil), map[string]string{"customerID": publicID.String()})
+		rec := httptest.NewRecorder()
+		newDocumentHandler(svc, customers).ListDocuments(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `[]`, rec.Body.String())
+		svc.AssertExpectations(t)
+	})
+}
+
+func TestDocumentHandlerRequestUpload(t *testing.T) {
+	customerSubject := document.Subject{Type: document.SubjectCustomer, ID: 7}
+	params := map[string]string{"customerID": "7"}
+
+	t.Run("returns the pending document and its upload link", func(t *testing.T) {
+		svc := new(MockDocumentService)
+		upload := document.Upload{Type: document.TypeIDCopy, FileName: "ktp.png", ContentType: "image/png", SizeBytes: 2048}
+		expires := time.Date(2025, 3, 3, 10, 15, 0, 0, time.UTC)
+		svc.On("RequestUpload", mock.Anything, customerSubject, upload).Return(&document.PendingUpload{
+			Document: document.Document{ID: 9, Subject: customerSubject, Type: document.TypeIDCopy, Status: document.StatusPending,
+				FileName: "ktp.png", ContentType: "image/png", SizeBytes: 2048},
+			URL:       "https://storage.example/upload",
+			ExpiresAt: expires,
+		}, nil).Once()
+
+		body := strings.NewReader(`{"type": "id_copy", "fileName": "ktp.png", "contentType": "image/png", "sizeBytes": 2048}`)
+		req := withURLParams(httptest.NewRequest(http.MethodPost, "/customers/7/documents", body), params)
+		rec := httptest.NewRecorder()
+		newDocumentHandler(svc, new(MockCustomerService)).RequestUpload(rec, req)
+
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		var resp dto.DocumentUploadResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, "9", resp.Document.ID)
+		assert.Equal(t, "PENDING", resp.Document.Status)
+		assert.Equal(t, "https://storage.example/upload", resp.UploadURL)
+		assert.True(t, expires.Equal(resp.UploadExpiresAt))
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("rejects an invalid request before the service", func(t *testing.T) {
+		svc := new(MockDocumentService)
+
+		body := strings.NewReader(`{"type": "ID_COPY", "fileName": "ktp.png", "contentType": "image/png", "sizeBytes": 0}`)
+		req := withURLParams(httptest.NewRequest(http.MethodPost, "/customers/7/documents", body), params)
+		rec := httptest.NewRecorder()
+		newDocumentHandler(svc, new(MockCustomerService)).RequestUpload(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "sizeBytes must be a positive number")
+		svc.AssertNotCalled(t, "RequestUpload", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("reports a type filed under the other subject", func(t *testing.T) {
+		svc := new(MockDocumentService)
+		svc.On("RequestUpload", mock.Anything, document.LoanSubject(42), mock.Anything).
+			Return(nil, fmt.Errorf("%w: ID_COPY documents are uploaded to a customer, not a loan", apperrors.ErrInvalidArgument)).Once()
+
+		body := strings.NewReader(`{"type": "ID_COPY", "fileName": "ktp.png", "contentType": "image/png", "sizeBytes": 2048}`)
+		req := withURLParams(httptest.NewRequest(http.MethodPost, "/loans/42/documents", body), map[string]string{"loanID": "42"})
+		rec := httptest.NewRecorder()
+		newDocumentHandler(svc, new(MockCustomerService)).RequestUpload(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestDocumentHandlerConfirmUpload(t *testing.T) {
+	params := map[string]string{"loanID": "42", "documentID": "9"}
+
+	t.Run("returns the available document", func(t *testing.T) {
+		svc := new(MockDocumentService)
+		svc.On("ConfirmUpload", mock.Anything, document.LoanSubject(42), int64(9)).Return(&document.Document{
+			ID: 9, Subject: document.LoanSubject(42), Type: document.TypeContract, Status: document.StatusAvailable, FileName: "contract.pdf",
+		}, nil).Once()
+
+		req := withURLParams(httptest.NewRequest(http.MethodPost, "/loans/42/documents/9/confirm", nil), params)
+		rec := httptest.NewRecorder()
+		newDocumentHandler(svc, new(MockCustomerService)).ConfirmUpload(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var resp dto.DocumentResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, "AVAILABLE", resp.Status)
+	})
+
+	t.Run("reports a file that was not uploaded", func(t *testing.T) {
+		svc := new(MockDocumentService)
+		svc.On("ConfirmUpload", mock.Anything, document.LoanSubject(42), int64(9)).
+			Return(nil, fmt.Errorf("%w: the file of document 9 has not been uploaded", apperrors.ErrConflict)).Once()
+
+		req := withURLParams(httptest.NewRequest(http.MethodPost, "/loans/42/documents/9/confirm", nil), params)
+		rec := httptest.NewRecorder()
+		newDocumentHandler(svc, new(MockCustomerService)).ConfirmUpload(rec, req)
+
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+}
+
+func TestDocumentHandlerDeleteDocument(t *testing.T) {
+	params := map[string]string{"loanID": "42", "documentID": "5"}
+
+	t.Run("deletes the document", func(t *testing.T) {
+		svc := new(MockDocumentService)
+		svc.On("Delete", mock.Anything, document.LoanSubject(42), int64(5)).Return(nil).Once()
+
+		req := withURLParams(httptest.NewRequest(http.MethodDelete, "/loans/42/documents/5", nil), params)
+		rec := httptest.NewRecorder()
+		newDocumentHandler(svc, new(MockCustomerService)).DeleteDocument(rec, req)
+
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("refuses a payoff certificate", func(t *testing.T) {
+		svc := new(MockDocumentService)
+		svc.On("Delete", mock.Anything, document.LoanSubject(42), int64(5)).
+			Return(fmt.Errorf("%w: payoff certificates cannot be deleted", apperrors.ErrConflict)).Once()
+
+		req := withURLParams(httptest.NewRequest(http.MethodDelete, "/loans/42/documents/5", nil), params)
+		rec := httptest.NewRecorder()
+		newDocumentHandler(svc, new(MockCustomerService)).DeleteDocument(rec, req)
+
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+}
+
+func TestDocumentHandlerDownloadDocument(t *testing.T) {
+	params := map[string]string{"loanID": "42", "documentID": "5"}
+
+	t.Run("sends the content as an attachment", func(t *testing.T) {
+		svc := new(MockDocumentService)
+		svc.On("Open", mock.Anything, document.LoanSubject(42), int64(5)).Return(&document.Document{
+			ID: 5, Subject: document.LoanSubject(42), FileName: "payoff-certificate-42.pdf", ContentType: document.ContentTypePDF, SizeBytes: 8,
+		}, io.NopCloser(strings.NewReader("%PDF-1.4")), nil).Once()
+
+		req := withURLParams(httptest.NewRequest(http.MethodGet, "/loans/42/documents/5", nil), params)
+		rec := httptest.NewRecorder()
+		newDocumentHandler(svc, new(MockCustomerService)).DownloadDocument(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.Equal(t, "application/pdf", rec.Header().Get("Content-Type"))
+		assert.Equal(t, "attachment; filename=payoff-certificate-42.pdf", rec.Header().Get("Content-Disposition"))
+		assert.Equal(t, "8", rec.Header().Get("Content-Length"))
+		assert.Equal(t, "%PDF-1.4", rec.Body.String())
+	})
+
+	t.Run("reports storage that is not configured", func(t *testing.T) {
+		svc := new(MockDocumentService)
+		svc.On("Open", mock.Anything, document.LoanSubject(42), int64(5)).Return(nil, nil, fmt.Errorf("%w: document storage is not configured", apperrors.ErrUnavailable)).Once()
+
+		req := withURLParams(httptest.NewRequest(http.MethodGet, "/loans/42/documents/5", nil), params)
+		rec := httptest.NewRecorder()
+		newDocumentHandler(svc, new(MockCustomerService)).DownloadDocument(rec, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	})
+
+	t.Run("rejects a malformed document ID", func(t *testing.T) {
+		svc := new(MockDocumentService)
+
+		req := withURLParams(httptest.NewRequest(http.MethodGet, "/loans/42/documents/x", nil), map[string]string{"loanID": "42", "documentID": "x"})
+		rec := httptest.NewRecorder()
+		newDocumentHandler(svc, new(MockCustomerService)).DownloadDocument(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		svc.AssertNotCalled(t, "Open", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
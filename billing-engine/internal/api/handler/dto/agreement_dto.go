@@ -0,0 +1,62 @@
+package dto
+
+import (
+	"billing-engine/internal/domain/agreement"
+	"billing-engine/internal/domain/loan"
+	"strconv"
+	"time"
+)
+
+// AgreementResponse describes the agreement of a loan created in workflow
+// mode. documentId is the unsigned agreement among the loan's documents,
+// left out once retention removed it; the signed copy stays with the
+// provider, under envelopeId. The loan can be disbursed once status is
+// COMPLETED, and disbursedAt is set when it was.
+type AgreementResponse struct {
+	ID          string     `json:"id"`
+	LoanID      string     `json:"loanId"`
+	DocumentID  string     `json:"documentId,omitempty"`
+	Provider    string     `json:"provider"`
+	EnvelopeID  string     `json:"envelopeId"`
+	Status      string     `json:"status"`
+	SentBy      string     `json:"sentBy,omitempty"`
+	SentAt      time.Time  `json:"sentAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+	SignedAt    *time.Time `json:"signedAt,omitempty"`
+	DisbursedBy string     `json:"disbursedBy,omitempty"`
+	DisbursedAt *time.Time `json:"disbursedAt,omitempty"`
+}
+
+func NewAgreementResponse(a *agreement.Agreement) AgreementResponse {
+	if a == nil {
+		return AgreementResponse{}
+	}
+	resp := AgreementResponse{
+		ID:          strconv.FormatInt(a.ID, 10),
+		LoanID:      strconv.FormatInt(a.LoanID, 10),
+		Provider:    a.Provider,
+		EnvelopeID:  a.EnvelopeID,
+		Status:      string(a.Status),
+		SentBy:      a.SentBy,
+		SentAt:      a.SentAt,
+		UpdatedAt:   a.UpdatedAt,
+		SignedAt:    a.SignedAt,
+		DisbursedBy: a.DisbursedBy,
+		DisbursedAt: a.DisbursedAt,
+	}
+	if a.DocumentID != nil {
+		resp.DocumentID = strconv.FormatInt(*a.DocumentID, 10)
+	}
+	return resp
+}
+
+// WorkflowLoanResponse is a loan created in workflow mode, on hold until it
+// is disbursed, and the agreement sent for its signature.
+type WorkflowLoanResponse struct {
+	Loan      LoanResponse      `json:"loan"`
+	Agreement AgreementResponse `json:"agreement"`
+}
+
+func NewWorkflowLoanResponse(l *loan.Loan, a *agreement.Agreement) WorkflowLoanResponse {
+	return WorkflowLoanResponse{Loan: NewLoanResponse(l, false), Agreement: NewAgreementResponse(a)}
+}
@@ -0,0 +1,315 @@
+package dto
+
+import (
+	"billing-engine/internal/domain/collections"
+	"billing-engine/internal/domain/loan"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type CreateCollectionRuleRequest struct {
+	Name      string `json:"name"`
+	Collector string `json:"collector"`
+	// Bucket is a days-past-due bucket: 1-30, 31-60, 61-90 or 90+.
+	Bucket string `json:"bucket,omitempty"`
+	// Region is matched against the customer's address.
+	Region         string  `json:"region,omitempty"`
+	MinOutstanding *string `json:"minOutstanding,omitempty"`
+	MaxOutstanding *string `json:"maxOutstanding,omitempty"`
+	Priority       int     `json:"priority"`
+}
+
+func (r *CreateCollectionRuleRequest) Validate() error {
+	if strings.TrimSpace(r.Name) == "" {
+		return fmt.Errorf("name cannot be empty")
+	}
+	if strings.TrimSpace(r.Collector) == "" {
+		return fmt.Errorf("collector cannot be empty")
+	}
+	if _, err := optionalAmount(r.MinOutstanding); err != nil {
+		return fmt.Errorf("invalid minOutstanding: %w", err)
+	}
+	if _, err := optionalAmount(r.MaxOutstanding); err != nil {
+		return fmt.Errorf("invalid maxOutstanding: %w", err)
+	}
+	return nil
+}
+
+// Rule converts the request. Call it after Validate.
+func (r *CreateCollectionRuleRequest) Rule() *collections.Rule {
+	minOutstanding, _ := optionalAmount(r.MinOutstanding)
+	maxOutstanding, _ := optionalAmount(r.MaxOutstanding)
+	return &collections.Rule{
+		Name:           r.Name,
+		Collector:      r.Collector,
+		Bucket:         r.Bucket,
+		Region:         r.Region,
+		MinOutstanding: minOutstanding,
+		MaxOutstanding: maxOutstanding,
+		Priority:       r.Priority,
+	}
+}
+
+func optionalAmount(s *string) (*loan.Money, error) {
+	if s == nil {
+		return nil, nil
+	}
+	d, err := decimal.NewFromString(strings.TrimSpace(*s))
+	if err != nil {
+		return nil, fmt.Errorf("amount must be a decimal number")
+	}
+	amount := d.InexactFloat64()
+	return &amount, nil
+}
+
+func optionalMoney(m *loan.Money) *string {
+	if m == nil {
+		return nil
+	}
+	s := formatMoney(*m)
+	return &s
+}
+
+type CollectionRuleResponse struct {
+	ID             string    `json:"id"`
+	Name           string    `json:"name"`
+	Collector      string    `json:"collector"`
+	Bucket         string    `json:"bucket,omitempty"`
+	Region         string    `json:"region,omitempty"`
+	MinOutstanding *string   `json:"minOutstanding,omitempty"`
+	MaxOutstanding *string   `json:"maxOutstanding,omitempty"`
+	Priority       int       `json:"priority"`
+	Active         bool      `json:"active"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+func NewCollectionRuleResponse(r *collections.Rule) CollectionRuleResponse {
+	if r == nil {
+		return CollectionRuleResponse{}
+	}
+	return CollectionRuleResponse{
+		ID:             strconv.FormatInt(r.ID, 10),
+		Name:           r.Name,
+		Collector:      r.Collector,
+		Bucket:         r.Bucket,
+		Region:         r.Region,
+		MinOutstanding: optionalMoney(r.MinOutstanding),
+		MaxOutstanding: optionalMoney(r.MaxOutstanding),
+		Priority:       r.Priority,
+		Active:         r.Active,
+		CreatedAt:      r.CreatedAt,
+		UpdatedAt:      r.UpdatedAt,
+	}
+}
+
+func NewCollectionRuleListResponse(rules []collections.Rule) []CollectionRuleResponse {
+	resp := make([]CollectionRuleResponse, 0, len(rules))
+	for i := range rules {
+		resp = append(resp, NewCollectionRuleResponse(&rules[i]))
+	}
+	return resp
+}
+
+type CollectionAssignmentResponse struct {
+	ID           string     `json:"id"`
+	LoanID       string     `json:"loanId"`
+	CustomerID   *string    `json:"customerId,omitempty"`
+	CustomerName string     `json:"customerName,omitempty"`
+	Collector    string     `json:"collector"`
+	RuleID       *string    `json:"ruleId,omitempty"`
+	Status       string     `json:"status"`
+	LoanStatus   string     `json:"loanStatus,omitempty"`
+	DaysPastDue  int        `json:"daysPastDue"`
+	Bucket       string     `json:"bucket"`
+	Outstanding  string     `json:"outstanding"`
+	AssignedAt   time.Time  `json:"assignedAt"`
+	LastActionAt *time.Time `json:"lastActionAt,omitempty"`
+}
+
+func NewCollectionAssignmentResponse(a *collections.Assignment) CollectionAssignmentResponse {
+	if a == nil {
+		return CollectionAssignmentResponse{}
+	}
+	resp := CollectionAssignmentResponse{
+		ID:           strconv.FormatInt(a.ID, 10),
+		LoanID:       strconv.FormatInt(a.LoanID, 10),
+		CustomerName: a.CustomerName,
+		Collector:    a.Collector,
+		Status:       string(a.Status),
+		LoanStatus:   string(a.LoanStatus),
+		DaysPastDue:  a.DaysPastDue,
+		Bucket:       loan.DPDBucket(a.DaysPastDue),
+		Outstanding:  formatMoney(a.Outstanding),
+		AssignedAt:   a.AssignedAt,
+		LastActionAt: a.LastActionAt,
+	}
+	if a.CustomerID != 0 {
+		id := strconv.FormatInt(a.CustomerID, 10)
+		resp.CustomerID = &id
+	}
+	if a.RuleID != nil {
+		id := strconv.FormatInt(*a.RuleID, 10)
+		resp.RuleID = &id
+	}
+	return resp
+}
+
+func NewCollectionQueueResponse(queue []collections.Assignment) []CollectionAssignmentResponse {
+	resp := make([]CollectionAssignmentResponse, 0, len(queue))
+	for i := range queue {
+		resp = append(resp, NewCollectionAssignmentResponse(&queue[i]))
+	}
+	return resp
+}
+
+type RecordCollectionActionRequest struct {
+	// Type is CALL, SMS, VISIT, PROMISE_TO_PAY or NOTE.
+	Type string `json:"type"`
+	Note string `json:"note,omitempty"`
+	// PromisedAmount and PromisedDate (YYYY-MM-DD) are required for
+	// PROMISE_TO_PAY.
+	PromisedAmount *string `json:"promisedAmount,omitempty"`
+	PromisedDate   *string `json:"promisedDate,omitempty"`
+}
+
+func (r *RecordCollectionActionRequest) Validate() error {
+	if strings.TrimSpace(r.Type) == "" {
+		return fmt.Errorf("type cannot be empty")
+	}
+	if _, err := optionalAmount(r.PromisedAmount); err != nil {
+		return fmt.Errorf("invalid promisedAmount: %w", err)
+	}
+	if r.PromisedDate != nil {
+		if _, err := time.Parse(time.DateOnly, *r.PromisedDate); err != nil {
+			return fmt.Errorf("invalid promisedDate format, use YYYY-MM-DD")
+		}
+	}
+	return nil
+}
+
+// Action converts the request. Call it after Validate.
+func (r *RecordCollectionActionRequest) Action() *collections.Action {
+	amount, _ := optionalAmount(r.PromisedAmount)
+	action := &collections.Action{
+		Type:           collections.ActionType(r.Type),
+		Note:           r.Note,
+		PromisedAmount: amount,
+	}
+	if r.PromisedDate != nil {
+		date, _ := time.Parse(time.DateOnly, *r.PromisedDate)
+		action.PromisedDate = &date
+	}
+	return action
+}
+
+type CollectionActionResponse struct {
+	ID             string    `json:"id"`
+	AssignmentID   string    `json:"assignmentId"`
+	LoanID         string    `json:"loanId"`
+	Collector      string    `json:"collector,omitempty"`
+	Type           string    `json:"type"`
+	Note           string    `json:"note,omitempty"`
+	PromisedAmount *string   `json:"promisedAmount,omitempty"`
+	PromisedDate   *string   `json:"promisedDate,omitempty"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+func NewCollectionActionResponse(a *collections.Action) CollectionActionResponse {
+	if a == nil {
+		return CollectionActionResponse{}
+	}
+	resp := CollectionActionResponse{
+		ID:             strconv.FormatInt(a.ID, 10),
+		AssignmentID:   strconv.FormatInt(a.AssignmentID, 10),
+		LoanID:         strconv.FormatInt(a.LoanID, 10),
+		Collector:      a.Collector,
+		Type:           string(a.Type),
+		Note:           a.Note,
+		PromisedAmount: optionalMoney(a.PromisedAmount),
+		CreatedAt:      a.CreatedAt,
+	}
+	if a.PromisedDate != nil {
+		date := a.PromisedDate.Format(time.DateOnly)
+		resp.PromisedDate = &date
+	}
+	return resp
+}
+
+func NewCollectionActionListResponse(actions []collections.Action) []CollectionActionResponse {
+	resp := make([]CollectionActionResponse, 0, len(actions))
+	for i := range actions {
+		resp = append(resp, NewCollectionActionResponse(&actions[i]))
+	}
+	return resp
+}
+
+type ReassignCollectionRequest struct {
+	Collector string `json:"collector"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+func (r *ReassignCollectionRequest) Validate() error {
+	if strings.TrimSpace(r.Collector) == "" {
+		return fmt.Errorf("collector cannot be empty")
+	}
+	return nil
+}
+
+type EscalationStepRequest struct {
+	DaysPastDue int `json:"daysPastDue"`
+	// Action is SMS, EMAIL, CALL or LETTER.
+	Action string `json:"action"`
+}
+
+type ReplaceEscalationStepsRequest struct {
+	// Steps replaces the whole ladder; an empty list turns reminders off.
+	Steps []EscalationStepRequest `json:"steps"`
+}
+
+func (r *ReplaceEscalationStepsRequest) Validate() error {
+	if r.Steps == nil {
+		return fmt.Errorf("steps is required, send an empty list to remove every step")
+	}
+	for i, s := range r.Steps {
+		if strings.TrimSpace(s.Action) == "" {
+			return fmt.Errorf("action of step %d cannot be empty", i+1)
+		}
+	}
+	return nil
+}
+
+// Ladder converts the request. Call it after Validate.
+func (r *ReplaceEscalationStepsRequest) Ladder() []collections.EscalationStep {
+	steps := make([]collections.EscalationStep, 0, len(r.Steps))
+	for _, s := range r.Steps {
+		steps = append(steps, collections.EscalationStep{DaysPastDue: s.DaysPastDue, Action: collections.ReminderAction(s.Action)})
+	}
+	return steps
+}
+
+type EscalationStepResponse struct {
+	DaysPastDue int    `json:"daysPastDue"`
+	Action      string `json:"action"`
+	// Notifies is true when the step reminds the customer and false when it
+	// is a task for the collections team.
+	Notifies  bool      `json:"notifies"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func NewEscalationStepListResponse(steps []collections.EscalationStep) []EscalationStepResponse {
+	resp := make([]EscalationStepResponse, 0, len(steps))
+	for _, s := range steps {
+		resp = append(resp, EscalationStepResponse{
+			DaysPastDue: s.DaysPastDue,
+			Action:      string(s.Action),
+			Notifies:    s.Notifies(),
+			CreatedAt:   s.CreatedAt,
+		})
+	}
+	return resp
+}
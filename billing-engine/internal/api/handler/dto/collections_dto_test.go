@@ -0,0 +1,58 @@
+package dto
+
+import (
+	"billing-engine/internal/domain/collections"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateCollectionRuleRequestValidate(t *testing.T) {
+	minOutstanding := "1000000.50"
+	valid := CreateCollectionRuleRequest{Name: "Jakarta early", Collector: "alice", Bucket: "1-30", MinOutstanding: &minOutstanding}
+	require.NoError(t, valid.Validate())
+	rule := valid.Rule()
+	require.NotNil(t, rule.MinOutstanding)
+	assert.Equal(t, 1000000.50, *rule.MinOutstanding)
+	assert.Nil(t, rule.MaxOutstanding)
+
+	noCollector := valid
+	noCollector.Collector = " "
+	assert.EqualError(t, noCollector.Validate(), "collector cannot be empty")
+
+	badAmount := "lots"
+	badMax := valid
+	badMax.MaxOutstanding = &badAmount
+	assert.EqualError(t, badMax.Validate(), "invalid maxOutstanding: amount must be a decimal number")
+}
+
+func TestRecordCollectionActionRequest(t *testing.T) {
+	amount, date := "250000", "2025-02-10"
+	req := RecordCollectionActionRequest{Type: "PROMISE_TO_PAY", PromisedAmount: &amount, PromisedDate: &date}
+	require.NoError(t, req.Validate())
+	action := req.Action()
+	assert.Equal(t, collections.ActionPromiseToPay, action.Type)
+	assert.Equal(t, 250000.0, *action.PromisedAmount)
+	assert.Equal(t, time.Date(2025, 2, 10, 0, 0, 0, 0, time.UTC), *action.PromisedDate)
+
+	badDate := "10/02/2025"
+	req.PromisedDate = &badDate
+	assert.EqualError(t, req.Validate(), "invalid promisedDate format, use YYYY-MM-DD")
+}
+
+func TestNewCollectionAssignmentResponse(t *testing.T) {
+	ruleID := int64(4)
+	resp := NewCollectionAssignmentResponse(&collections.Assignment{
+		ID: 9, LoanID: 3, CustomerID: 5, Collector: "alice", RuleID: &ruleID,
+		Status: collections.AssignmentOpen, DaysPastDue: 45, Outstanding: 1500000,
+	})
+
+	assert.Equal(t, "9", resp.ID)
+	assert.Equal(t, "31-60", resp.Bucket)
+	assert.Equal(t, "1500000.00", resp.Outstanding)
+	require.NotNil(t, resp.RuleID)
+	assert.Equal(t, "4", *resp.RuleID)
+	assert.NotNil(t, NewCollectionQueueResponse(nil))
+}
@@ -0,0 +1,96 @@
+package dto
+
+import (
+	"billing-engine/internal/domain/contact"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type CreateContactRequest struct {
+	// Type is "phone" or "email".
+	Type string `json:"type"`
+	// Value is a phone number in international form, such as +6281234567890,
+	// or an email address.
+	Value string `json:"value"`
+}
+
+func (r *CreateContactRequest) Validate() error {
+	if strings.TrimSpace(r.Type) == "" {
+		return fmt.Errorf("type cannot be empty")
+	}
+	if strings.TrimSpace(r.Value) == "" {
+		return fmt.Errorf("value cannot be empty")
+	}
+	return nil
+}
+
+type UpdateContactRequest struct {
+	// Value replaces the contact's value; a changed value is unverified
+	// until confirmed again.
+	Value   string `json:"value"`
+	Primary bool   `json:"primary"`
+}
+
+func (r *UpdateContactRequest) Validate() error {
+	if strings.TrimSpace(r.Value) == "" {
+		return fmt.Errorf("value cannot be empty")
+	}
+	return nil
+}
+
+type ConfirmContactRequest struct {
+	Code string `json:"code"`
+}
+
+func (r *ConfirmContactRequest) Validate() error {
+	if strings.TrimSpace(r.Code) == "" {
+		return fmt.Errorf("code cannot be empty")
+	}
+	return nil
+}
+
+type ContactResponse struct {
+	ID         string     `json:"id"`
+	CustomerID string     `json:"customerId"`
+	Type       string     `json:"type"`
+	Value      string     `json:"value"`
+	Verified   bool       `json:"verified"`
+	Primary    bool       `json:"primary"`
+	VerifiedAt *time.Time `json:"verifiedAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+}
+
+func NewContactResponse(c *contact.Contact) ContactResponse {
+	if c == nil {
+		return ContactResponse{}
+	}
+	return ContactResponse{
+		ID:         strconv.FormatInt(c.ID, 10),
+		CustomerID: strconv.FormatInt(c.CustomerID, 10),
+		Type:       string(c.Type),
+		Value:      c.Value,
+		Verified:   c.Verified,
+		Primary:    c.Primary,
+		VerifiedAt: c.VerifiedAt,
+		CreatedAt:  c.CreatedAt,
+		UpdatedAt:  c.UpdatedAt,
+	}
+}
+
+func NewContactListResponse(contacts []contact.Contact) []ContactResponse {
+	resp := make([]ContactResponse, 0, len(contacts))
+	for i := range contacts {
+		resp = append(resp, NewContactResponse(&contacts[i]))
+	}
+	return resp
+}
+
+// ContactVerificationResponse says until when the code just sent can be
+// confirmed. The code itself only reaches the contact.
+type ContactVerificationResponse struct {
+	ContactID string    `json:"contactId"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
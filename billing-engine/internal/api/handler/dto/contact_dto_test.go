@@ -0,0 +1,32 @@
+package dto
+
+import (
+	"billing-engine/internal/domain/contact"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContactRequestsValidate(t *testing.T) {
+	assert.NoError(t, (&CreateContactRequest{Type: "phone", Value: "+6281234567890"}).Validate())
+	assert.EqualError(t, (&CreateContactRequest{Value: "+6281234567890"}).Validate(), "type cannot be empty")
+	assert.EqualError(t, (&CreateContactRequest{Type: "email", Value: " "}).Validate(), "value cannot be empty")
+	assert.EqualError(t, (&UpdateContactRequest{Primary: true}).Validate(), "value cannot be empty")
+	assert.EqualError(t, (&ConfirmContactRequest{Code: " "}).Validate(), "code cannot be empty")
+}
+
+func TestNewContactListResponse(t *testing.T) {
+	verifiedAt := time.Date(2025, 3, 5, 9, 0, 0, 0, time.UTC)
+	resp := NewContactListResponse([]contact.Contact{
+		{ID: 12, CustomerID: 7, Type: contact.TypePhone, Value: "+6281234567890", Verified: true, Primary: true, VerifiedAt: &verifiedAt},
+	})
+
+	require.Len(t, resp, 1)
+	assert.Equal(t, "12", resp[0].ID)
+	assert.Equal(t, "7", resp[0].CustomerID)
+	assert.Equal(t, "phone", resp[0].Type)
+	assert.True(t, resp[0].Primary)
+	assert.NotNil(t, NewContactListResponse(nil), "empty lists render as [] not null")
+}
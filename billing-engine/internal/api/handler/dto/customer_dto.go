@@ -6,11 +6,18 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
 )
 
 type CreateCustomerRequest struct {
-	Name    string `json:"name"`
-	Address string `json:"address"`
+	Name        string `json:"name"`
+	Address     string `json:"address"`
+	ExternalRef string `json:"externalRef,omitempty"`
+	PublicID    string `json:"publicId,omitempty"`
+	// Force creates the customer even when it repeats an existing one.
+	Force bool `json:"force,omitempty"`
 }
 
 func (r *CreateCustomerRequest) Validate() error {
@@ -20,10 +27,53 @@ func (r *CreateCustomerRequest) Validate() error {
 	if strings.TrimSpace(r.Address) == "" {
 		return fmt.Errorf("address cannot be empty")
 	}
+	if err := validateExternalRef(r.ExternalRef); err != nil {
+		return err
+	}
+	if _, err := parsePublicID(r.PublicID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// PublicUUID returns the client-chosen public ID, or uuid.Nil when the server
+// should generate one. Call it after Validate.
+func (r *CreateCustomerRequest) PublicUUID() uuid.UUID {
+	id, _ := parsePublicID(r.PublicID)
+	return id
+}
 
+// validateExternalRef accepts an empty reference, which leaves the column
+// NULL.
+func validateExternalRef(ref string) error {
+	if utf8.RuneCountInString(strings.TrimSpace(ref)) > customer.MaxExternalRefLength {
+		return fmt.Errorf("externalRef cannot exceed %d characters", customer.MaxExternalRefLength)
+	}
 	return nil
 }
 
+func parsePublicID(raw string) (uuid.UUID, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return uuid.Nil, nil
+	}
+	id, err := uuid.Parse(raw)
+	if err != nil || id == uuid.Nil {
+		return uuid.Nil, fmt.Errorf("publicId must be a non-nil UUID")
+	}
+	return id, nil
+}
+
+// publicIDString leaves the field empty for records loaded without a public
+// ID, so responses never show the nil UUID.
+func publicIDString(id uuid.UUID) string {
+	if id == uuid.Nil {
+		return ""
+	}
+	return id.String()
+}
+
 type UpdateCustomerAddressRequest struct {
 	Address string `json:"address"`
 }
@@ -55,15 +105,52 @@ func (r *UpdateDelinquencyRequest) Validate() error {
 	return nil
 }
 
+// UpdateRiskScoreRequest is a credit bureau's assessment of a customer.
+// scoredAt defaults to the time it is stored.
+type UpdateRiskScoreRequest struct {
+	Score    *int       `json:"score"`
+	Grade    string     `json:"grade"`
+	ScoredAt *time.Time `json:"scoredAt,omitempty"`
+}
+
+func (r *UpdateRiskScoreRequest) Validate() error {
+	if r.Score == nil {
+		return fmt.Errorf("score is required")
+	}
+	if strings.TrimSpace(r.Grade) == "" {
+		return fmt.Errorf("grade cannot be empty")
+	}
+	return nil
+}
+
+// RiskAssessment returns the assessment for the customer. Call it after
+// Validate.
+func (r *UpdateRiskScoreRequest) RiskAssessment(customerID int64) customer.RiskAssessment {
+	a := customer.RiskAssessment{CustomerID: customerID, Score: *r.Score, Grade: r.Grade}
+	if r.ScoredAt != nil {
+		a.ScoredAt = *r.ScoredAt
+	}
+	return a
+}
+
+// CustomerResponse leaves out the risk fields until the customer was first
+// scored, and the merge fields unless it was merged into another customer.
 type CustomerResponse struct {
-	CustomerID   string    `json:"customerId"`
-	Name         string    `json:"name"`
-	Address      string    `json:"address"`
-	IsDelinquent bool      `json:"isDelinquent"`
-	Active       bool      `json:"active"`
-	LoanID       *string   `json:"loanId,omitempty"`
-	CreateDate   time.Time `json:"createDate"`
-	UpdatedAt    time.Time `json:"updatedAt"`
+	CustomerID   string     `json:"customerId"`
+	PublicID     string     `json:"publicId,omitempty"`
+	Name         string     `json:"name"`
+	Address      string     `json:"address"`
+	IsDelinquent bool       `json:"isDelinquent"`
+	Active       bool       `json:"active"`
+	LoanID       *string    `json:"loanId,omitempty"`
+	ExternalRef  *string    `json:"externalRef,omitempty"`
+	RiskScore    *int       `json:"riskScore,omitempty"`
+	RiskGrade    *string    `json:"riskGrade,omitempty"`
+	RiskScoredAt *time.Time `json:"riskScoredAt,omitempty"`
+	MergedIntoID *string    `json:"mergedIntoId,omitempty"`
+	MergedAt     *time.Time `json:"mergedAt,omitempty"`
+	CreateDate   time.Time  `json:"createDate"`
+	UpdatedAt    time.Time  `json:"updatedAt"`
 }
 
 func NewCustomerResponse(cust *customer.Customer) CustomerResponse {
@@ -72,21 +159,83 @@ func NewCustomerResponse(cust *customer.Customer) CustomerResponse {
 		return CustomerResponse{}
 	}
 
-	var loanIDStr *string
-
-	if cust.LoanID != nil {
-		s := strconv.FormatInt(*cust.LoanID, 10)
-		loanIDStr = &s
-	}
+	loanIDStr := formatOptionalID(cust.LoanID)
 
 	return CustomerResponse{
 		CustomerID:   strconv.FormatInt(cust.CustomerID, 10),
+		PublicID:     publicIDString(cust.PublicID),
 		Name:         cust.Name,
 		Address:      cust.Address,
 		IsDelinquent: cust.IsDelinquent,
 		Active:       cust.Active,
 		LoanID:       loanIDStr,
+		ExternalRef:  cust.ExternalRef,
+		RiskScore:    cust.RiskScore,
+		RiskGrade:    cust.RiskGrade,
+		RiskScoredAt: cust.RiskScoredAt,
+		MergedIntoID: formatOptionalID(cust.MergedIntoID),
+		MergedAt:     cust.MergedAt,
 		CreateDate:   cust.CreateDate,
 		UpdatedAt:    cust.UpdatedAt,
 	}
 }
+
+func formatOptionalID(id *int64) *string {
+	if id == nil {
+		return nil
+	}
+	s := strconv.FormatInt(*id, 10)
+	return &s
+}
+
+// MergeCustomerRequest names the duplicate customer to merge into the one in
+// the path.
+type MergeCustomerRequest struct {
+	SourceID int64 `json:"sourceId"`
+}
+
+func (r *MergeCustomerRequest) Validate() error {
+	if r.SourceID <= 0 {
+		return fmt.Errorf("sourceId must be a positive number")
+	}
+	return nil
+}
+
+// MergedRecordsResponse counts the records of the source that were moved to
+// the target, per kind.
+type MergedRecordsResponse struct {
+	Notes                 int64 `json:"notes"`
+	Attachments           int64 `json:"attachments"`
+	Documents             int64 `json:"documents"`
+	Mandates              int64 `json:"mandates"`
+	CollectionAssignments int64 `json:"collectionAssignments"`
+	ArchivedLoans         int64 `json:"archivedLoans"`
+	Events                int64 `json:"events"`
+}
+
+// CustomerMergeResponse holds both customers as stored after the merge.
+// loanId is the loan that moved to the target, omitted when the source had
+// none.
+type CustomerMergeResponse struct {
+	Target CustomerResponse      `json:"target"`
+	Source CustomerResponse      `json:"source"`
+	LoanID *string               `json:"loanId,omitempty"`
+	Moved  MergedRecordsResponse `json:"moved"`
+}
+
+func NewCustomerMergeResponse(m *customer.Merge) CustomerMergeResponse {
+	return CustomerMergeResponse{
+		Target: NewCustomerResponse(m.Target),
+		Source: NewCustomerResponse(m.Source),
+		LoanID: formatOptionalID(m.LoanID),
+		Moved: MergedRecordsResponse{
+			Notes:                 m.Moved.Notes,
+			Attachments:           m.Moved.Attachments,
+			Documents:             m.Moved.Documents,
+			Mandates:              m.Moved.Mandates,
+			CollectionAssignments: m.Moved.CollectionAssignments,
+			ArchivedLoans:         m.Moved.ArchivedLoans,
+			Events:                m.Moved.Events,
+		},
+	}
+}
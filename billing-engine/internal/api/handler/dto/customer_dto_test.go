@@ -3,9 +3,11 @@ package dto
 import (
 	"billing-engine/internal/domain/customer"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -23,6 +25,11 @@ func TestCreateCustomerRequestValidate(t *testing.T) {
 		{"Empty name", CreateCustomerRequest{Name: "", Address: "123 Street"}, true},
 		{"Empty address", CreateCustomerRequest{Name: "John Doe", Address: ""}, true},
 		{"Empty name and address", CreateCustomerRequest{Name: "", Address: ""}, true},
+		{"With external reference", CreateCustomerRequest{Name: "John Doe", Address: "123 Street", ExternalRef: "CRM-0001"}, false},
+		{"External reference too long", CreateCustomerRequest{Name: "John Doe", Address: "123 Street", ExternalRef: strings.Repeat("x", 65)}, true},
+		{"With public ID", CreateCustomerRequest{Name: "John Doe", Address: "123 Street", PublicID: "7b2f6a6e-1c4d-4f7e-9a35-0d8e2c1b5f44"}, false},
+		{"Malformed public ID", CreateCustomerRequest{Name: "John Doe", Address: "123 Street", PublicID: "not-a-uuid"}, true},
+		{"Nil public ID", CreateCustomerRequest{Name: "John Doe", Address: "123 Street", PublicID: uuid.Nil.String()}, true},
 	}
 
 	for _, tt := range tests {
@@ -87,10 +94,25 @@ func TestUpdateDelinquencyRequestValidate(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestUpdateRiskScoreRequest(t *testing.T) {
+	score := 712
+	scoredAt := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+
+	assert.Error(t, (&UpdateRiskScoreRequest{Grade: "A"}).Validate())
+	assert.Error(t, (&UpdateRiskScoreRequest{Score: &score, Grade: " "}).Validate())
+
+	req := UpdateRiskScoreRequest{Score: &score, Grade: "B+", ScoredAt: &scoredAt}
+	assert.NoError(t, req.Validate())
+	assert.Equal(t, customer.RiskAssessment{CustomerID: 9, Score: 712, Grade: "B+", ScoredAt: scoredAt}, req.RiskAssessment(9))
+	req.ScoredAt = nil
+	assert.True(t, req.RiskAssessment(9).ScoredAt.IsZero())
+}
+
 func TestNewCustomerResponse(t *testing.T) {
 	loanID := int64(123)
 	cust := &customer.Customer{
 		CustomerID:   1,
+		PublicID:     uuid.MustParse("7b2f6a6e-1c4d-4f7e-9a35-0d8e2c1b5f44"),
 		Name:         "John Doe",
 		Address:      "123 Street",
 		IsDelinquent: false,
@@ -102,6 +124,7 @@ func TestNewCustomerResponse(t *testing.T) {
 
 	resp := NewCustomerResponse(cust)
 	assert.Equal(t, strconv.FormatInt(cust.CustomerID, 10), resp.CustomerID)
+	assert.Equal(t, "7b2f6a6e-1c4d-4f7e-9a35-0d8e2c1b5f44", resp.PublicID)
 	assert.Equal(t, cust.Name, resp.Name)
 	assert.Equal(t, cust.Address, resp.Address)
 	assert.Equal(t, cust.IsDelinquent, resp.IsDelinquent)
@@ -110,6 +133,15 @@ func TestNewCustomerResponse(t *testing.T) {
 	assert.Equal(t, strconv.FormatInt(*cust.LoanID, 10), *resp.LoanID)
 	assert.Equal(t, cust.CreateDate, resp.CreateDate)
 	assert.Equal(t, cust.UpdatedAt, resp.UpdatedAt)
+	assert.Nil(t, resp.RiskScore)
+	assert.Nil(t, resp.RiskGrade)
+
+	score, grade := 712, "B+"
+	cust.RiskScore, cust.RiskGrade, cust.RiskScoredAt = &score, &grade, &cust.UpdatedAt
+	resp = NewCustomerResponse(cust)
+	assert.Equal(t, 712, *resp.RiskScore)
+	assert.Equal(t, "B+", *resp.RiskGrade)
+	assert.Equal(t, cust.UpdatedAt, *resp.RiskScoredAt)
 
 	resp = NewCustomerResponse(nil)
 	assert.Equal(t, CustomerResponse{}, resp)
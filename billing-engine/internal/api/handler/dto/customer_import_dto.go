@@ -0,0 +1,62 @@
+package dto
+
+import (
+	"billing-engine/internal/domain/customer"
+	"strconv"
+)
+
+// CustomerImportRow is one NDJSON line of a customer import upload. CSV
+// uploads use the same fields as header columns, with external_ref for the
+// reference.
+type CustomerImportRow struct {
+	Name        string `json:"name"`
+	Address     string `json:"address"`
+	ExternalRef string `json:"externalRef"`
+}
+
+type CustomerImportRowResult struct {
+	Line        int     `json:"line"`
+	ExternalRef string  `json:"externalRef,omitempty"`
+	Status      string  `json:"status"`
+	CustomerID  *string `json:"customerId,omitempty"`
+	Error       string  `json:"error,omitempty"`
+}
+
+type CustomerImportResponse struct {
+	Total      int                       `json:"total"`
+	Created    int                       `json:"created"`
+	Duplicates int                       `json:"duplicates"`
+	Invalid    int                       `json:"invalid"`
+	Failed     int                       `json:"failed"`
+	Results    []CustomerImportRowResult `json:"results"`
+}
+
+func NewCustomerImportResponse(report *customer.ImportReport) CustomerImportResponse {
+	if report == nil {
+		return CustomerImportResponse{Results: []CustomerImportRowResult{}}
+	}
+
+	results := make([]CustomerImportRowResult, 0, len(report.Results))
+	for _, r := range report.Results {
+		result := CustomerImportRowResult{
+			Line:        r.Line,
+			ExternalRef: r.ExternalRef,
+			Status:      r.Status,
+			Error:       r.Error,
+		}
+		if r.CustomerID != 0 {
+			id := strconv.FormatInt(r.CustomerID, 10)
+			result.CustomerID = &id
+		}
+		results = append(results, result)
+	}
+
+	return CustomerImportResponse{
+		Total:      report.Total,
+		Created:    report.Created,
+		Duplicates: report.Duplicates,
+		Invalid:    report.Invalid,
+		Failed:     report.Failed,
+		Results:    results,
+	}
+}
@@ -0,0 +1,33 @@
+package dto
+
+import (
+	"billing-engine/internal/domain/customer"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCustomerImportResponse(t *testing.T) {
+	resp := NewCustomerImportResponse(&customer.ImportReport{
+		Total: 2, Created: 1, Duplicates: 1,
+		Results: []customer.ImportResult{
+			{Line: 2, ExternalRef: "crm-1", Status: customer.ImportStatusCreated, CustomerID: 7},
+			{Line: 3, ExternalRef: "crm-1", Status: customer.ImportStatusDuplicate, Error: "externalRef already used on line 2"},
+		},
+	})
+
+	assert.Equal(t, 2, resp.Total)
+	assert.Equal(t, 1, resp.Duplicates)
+	require.Len(t, resp.Results, 2)
+	require.NotNil(t, resp.Results[0].CustomerID)
+	assert.Equal(t, "7", *resp.Results[0].CustomerID)
+	assert.Nil(t, resp.Results[1].CustomerID)
+	assert.Equal(t, "externalRef already used on line 2", resp.Results[1].Error)
+}
+
+func TestNewCustomerImportResponseNil(t *testing.T) {
+	resp := NewCustomerImportResponse(nil)
+	assert.NotNil(t, resp.Results)
+	assert.Empty(t, resp.Results)
+}
@@ -0,0 +1,51 @@
+package dto
+
+import (
+	"billing-engine/internal/domain/customer"
+	"strconv"
+	"time"
+)
+
+// UpdatePreferencesRequest replaces every preference. An omitted channel or
+// language clears it, and an omitted opt-out opts back in.
+type UpdatePreferencesRequest struct {
+	PreferredChannel    string `json:"preferredChannel,omitempty"`
+	Language            string `json:"language,omitempty"`
+	MarketingOptOut     bool   `json:"marketingOptOut"`
+	TransactionalOptOut bool   `json:"transactionalOptOut"`
+}
+
+func (r *UpdatePreferencesRequest) Preferences(customerID int64) customer.Preferences {
+	return customer.Preferences{
+		CustomerID:          customerID,
+		PreferredChannel:    r.PreferredChannel,
+		Language:            r.Language,
+		MarketingOptOut:     r.MarketingOptOut,
+		TransactionalOptOut: r.TransactionalOptOut,
+	}
+}
+
+// PreferencesResponse has no updatedAt until preferences were first set.
+type PreferencesResponse struct {
+	CustomerID          string     `json:"customerId"`
+	PreferredChannel    string     `json:"preferredChannel,omitempty"`
+	Language            string     `json:"language,omitempty"`
+	MarketingOptOut     bool       `json:"marketingOptOut"`
+	TransactionalOptOut bool       `json:"transactionalOptOut"`
+	UpdatedAt           *time.Time `json:"updatedAt,omitempty"`
+}
+
+func NewPreferencesResponse(p *customer.Preferences) PreferencesResponse {
+	resp := PreferencesResponse{
+		CustomerID:          strconv.FormatInt(p.CustomerID, 10),
+		PreferredChannel:    p.PreferredChannel,
+		Language:            p.Language,
+		MarketingOptOut:     p.MarketingOptOut,
+		TransactionalOptOut: p.TransactionalOptOut,
+	}
+	if !p.UpdatedAt.IsZero() {
+		updatedAt := p.UpdatedAt
+		resp.UpdatedAt = &updatedAt
+	}
+	return resp
+}
@@ -0,0 +1,33 @@
+package dto
+
+import (
+	"billing-engine/internal/infrastructure/monitoring"
+	"time"
+)
+
+// DeprecatedUsageResponse is how often one client called one deprecated
+// route since the instance started. Client is the token's subject, or the
+// IP of calls made without a token.
+type DeprecatedUsageResponse struct {
+	Method    string    `json:"method" example:"GET"`
+	Route     string    `json:"route" example:"/loans/{loanID}"`
+	Client    string    `json:"client" example:"collections-batch"`
+	Requests  uint64    `json:"requests"`
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+}
+
+func NewDeprecatedUsageListResponse(usage []monitoring.DeprecatedUsage) []DeprecatedUsageResponse {
+	resp := make([]DeprecatedUsageResponse, len(usage))
+	for i, u := range usage {
+		resp[i] = DeprecatedUsageResponse{
+			Method:    u.Method,
+			Route:     u.Route,
+			Client:    u.Client,
+			Requests:  u.Requests,
+			FirstSeen: u.FirstSeen,
+			LastSeen:  u.LastSeen,
+		}
+	}
+	return resp
+}
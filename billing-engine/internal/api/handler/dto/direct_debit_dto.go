@@ -0,0 +1,135 @@
+package dto
+
+import (
+	"billing-engine/internal/domain/directdebit"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type CreateMandateRequest struct {
+	// Reference is the mandate ID agreed with the bank; one is generated
+	// when it is left empty.
+	Reference     string `json:"reference,omitempty"`
+	AccountHolder string `json:"accountHolder"`
+	AccountNumber string `json:"accountNumber"`
+	BankCode      string `json:"bankCode"`
+	SignedAt      string `json:"signedAt"`
+}
+
+func (r *CreateMandateRequest) Validate() error {
+	if strings.TrimSpace(r.AccountHolder) == "" {
+		return fmt.Errorf("accountHolder cannot be empty")
+	}
+	if strings.TrimSpace(r.AccountNumber) == "" {
+		return fmt.Errorf("accountNumber cannot be empty")
+	}
+	if strings.TrimSpace(r.BankCode) == "" {
+		return fmt.Errorf("bankCode cannot be empty")
+	}
+	if _, err := time.Parse(time.DateOnly, r.SignedAt); err != nil {
+		return fmt.Errorf("invalid signedAt format, use YYYY-MM-DD")
+	}
+	return nil
+}
+
+// Mandate converts the request for the given customer. Call it after
+// Validate.
+func (r *CreateMandateRequest) Mandate(customerID int64) *directdebit.Mandate {
+	signedAt, _ := time.Parse(time.DateOnly, r.SignedAt)
+	return &directdebit.Mandate{
+		CustomerID:    customerID,
+		Reference:     r.Reference,
+		AccountHolder: r.AccountHolder,
+		AccountNumber: r.AccountNumber,
+		BankCode:      r.BankCode,
+		SignedAt:      signedAt,
+	}
+}
+
+type MandateResponse struct {
+	ID            string    `json:"id"`
+	CustomerID    string    `json:"customerId"`
+	Reference     string    `json:"reference"`
+	AccountHolder string    `json:"accountHolder"`
+	AccountNumber string    `json:"accountNumber"`
+	BankCode      string    `json:"bankCode"`
+	Status        string    `json:"status"`
+	SignedAt      string    `json:"signedAt"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+func NewMandateResponse(m *directdebit.Mandate) MandateResponse {
+	if m == nil {
+		return MandateResponse{}
+	}
+	return MandateResponse{
+		ID:            strconv.FormatInt(m.ID, 10),
+		CustomerID:    strconv.FormatInt(m.CustomerID, 10),
+		Reference:     m.Reference,
+		AccountHolder: m.AccountHolder,
+		AccountNumber: m.AccountNumber,
+		BankCode:      m.BankCode,
+		Status:        string(m.Status),
+		SignedAt:      m.SignedAt.Format(time.DateOnly),
+		CreatedAt:     m.CreatedAt,
+		UpdatedAt:     m.UpdatedAt,
+	}
+}
+
+func NewMandateListResponse(mandates []directdebit.Mandate) []MandateResponse {
+	resp := make([]MandateResponse, 0, len(mandates))
+	for i := range mandates {
+		resp = append(resp, NewMandateResponse(&mandates[i]))
+	}
+	return resp
+}
+
+type DirectDebitResultOutcome struct {
+	Line       int     `json:"line"`
+	EndToEndID string  `json:"endToEndId"`
+	Status     string  `json:"status"`
+	LoanID     *string `json:"loanId,omitempty"`
+	Error      string  `json:"error,omitempty"`
+}
+
+type DirectDebitResultsResponse struct {
+	Total     int                        `json:"total"`
+	Collected int                        `json:"collected"`
+	Failed    int                        `json:"failed"`
+	Unapplied int                        `json:"unapplied"`
+	Skipped   int                        `json:"skipped"`
+	Results   []DirectDebitResultOutcome `json:"results"`
+}
+
+func NewDirectDebitResultsResponse(report *directdebit.ResultReport) DirectDebitResultsResponse {
+	if report == nil {
+		return DirectDebitResultsResponse{Results: []DirectDebitResultOutcome{}}
+	}
+
+	results := make([]DirectDebitResultOutcome, 0, len(report.Results))
+	for _, r := range report.Results {
+		result := DirectDebitResultOutcome{
+			Line:       r.Line,
+			EndToEndID: r.EndToEndID,
+			Status:     r.Status,
+			Error:      r.Error,
+		}
+		if r.LoanID != 0 {
+			id := strconv.FormatInt(r.LoanID, 10)
+			result.LoanID = &id
+		}
+		results = append(results, result)
+	}
+
+	return DirectDebitResultsResponse{
+		Total:     report.Total,
+		Collected: report.Collected,
+		Failed:    report.Failed,
+		Unapplied: report.Unapplied,
+		Skipped:   report.Skipped,
+		Results:   results,
+	}
+}
@@ -0,0 +1,43 @@
+package dto
+
+import (
+	"billing-engine/internal/domain/directdebit"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateMandateRequestValidate(t *testing.T) {
+	valid := CreateMandateRequest{AccountHolder: "Jane Doe", AccountNumber: "9876543210", BankCode: "BANKIDJB", SignedAt: "2025-01-02"}
+	require.NoError(t, valid.Validate())
+	m := valid.Mandate(7)
+	assert.Equal(t, int64(7), m.CustomerID)
+	assert.Equal(t, time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC), m.SignedAt)
+
+	missingBank := valid
+	missingBank.BankCode = " "
+	assert.EqualError(t, missingBank.Validate(), "bankCode cannot be empty")
+
+	badDate := valid
+	badDate.SignedAt = "02/01/2025"
+	assert.EqualError(t, badDate.Validate(), "invalid signedAt format, use YYYY-MM-DD")
+}
+
+func TestNewDirectDebitResultsResponse(t *testing.T) {
+	resp := NewDirectDebitResultsResponse(&directdebit.ResultReport{
+		Total: 2, Collected: 1, Skipped: 1,
+		Results: []directdebit.ResultOutcome{
+			{Line: 2, EndToEndID: "E2E1", Status: directdebit.ResultStatusCollected, LoanID: 5},
+			{Line: 3, EndToEndID: "E2E2", Status: directdebit.ResultStatusSkipped, Error: "unknown end_to_end_id"},
+		},
+	})
+
+	assert.Equal(t, 1, resp.Collected)
+	require.Len(t, resp.Results, 2)
+	require.NotNil(t, resp.Results[0].LoanID)
+	assert.Equal(t, "5", *resp.Results[0].LoanID)
+	assert.Nil(t, resp.Results[1].LoanID)
+	assert.NotNil(t, NewDirectDebitResultsResponse(nil).Results)
+}
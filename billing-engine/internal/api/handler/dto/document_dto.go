@@ -0,0 +1,98 @@
+package dto
+
+import (
+	"billing-engine/internal/domain/document"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CreateDocumentRequest announces a file the client is about to upload.
+// sizeBytes is the exact length of the file and contentType the
+// Content-Type it is uploaded with.
+type CreateDocumentRequest struct {
+	Type        string `json:"type"`
+	FileName    string `json:"fileName"`
+	ContentType string `json:"contentType"`
+	SizeBytes   int64  `json:"sizeBytes"`
+}
+
+func (r *CreateDocumentRequest) Validate() error {
+	if _, err := document.ParseType(r.Type); err != nil {
+		return err
+	}
+	if strings.TrimSpace(r.FileName) == "" {
+		return fmt.Errorf("fileName cannot be empty")
+	}
+	if strings.TrimSpace(r.ContentType) == "" {
+		return fmt.Errorf("contentType cannot be empty")
+	}
+	if r.SizeBytes <= 0 {
+		return fmt.Errorf("sizeBytes must be a positive number")
+	}
+	return nil
+}
+
+// DocumentResponse describes a document of a loan or customer, generated by
+// the engine, such as a payoff certificate, or uploaded by staff. Its
+// content is downloaded from the document's own route once its status is
+// AVAILABLE. The storage key is internal and not exposed.
+type DocumentResponse struct {
+	ID          string    `json:"id"`
+	SubjectType string    `json:"subjectType"`
+	SubjectID   string    `json:"subjectId"`
+	Type        string    `json:"type"`
+	Status      string    `json:"status"`
+	FileName    string    `json:"fileName"`
+	ContentType string    `json:"contentType"`
+	SizeBytes   int64     `json:"sizeBytes"`
+	UploadedBy  string    `json:"uploadedBy,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+func NewDocumentResponse(d *document.Document) DocumentResponse {
+	if d == nil {
+		return DocumentResponse{}
+	}
+	return DocumentResponse{
+		ID:          strconv.FormatInt(d.ID, 10),
+		SubjectType: string(d.Subject.Type),
+		SubjectID:   strconv.FormatInt(d.Subject.ID, 10),
+		Type:        string(d.Type),
+		Status:      string(d.Status),
+		FileName:    d.FileName,
+		ContentType: d.ContentType,
+		SizeBytes:   d.SizeBytes,
+		UploadedBy:  d.UploadedBy,
+		CreatedAt:   d.CreatedAt,
+	}
+}
+
+func NewDocumentListResponse(documents []document.Document) []DocumentResponse {
+	resp := make([]DocumentResponse, 0, len(documents))
+	for i := range documents {
+		resp = append(resp, NewDocumentResponse(&documents[i]))
+	}
+	return resp
+}
+
+// DocumentUploadResponse holds the pending document and the link its file
+// is sent to with a PUT, carrying the document's contentType as its
+// Content-Type, before uploadExpiresAt.
+type DocumentUploadResponse struct {
+	Document        DocumentResponse `json:"document"`
+	UploadURL       string           `json:"uploadUrl"`
+	UploadExpiresAt time.Time        `json:"uploadExpiresAt"`
+}
+
+func NewDocumentUploadResponse(p *document.PendingUpload) DocumentUploadResponse {
+	if p == nil {
+		return DocumentUploadResponse{}
+	}
+	return DocumentUploadResponse{
+		Document:        NewDocumentResponse(&p.Document),
+		UploadURL:       p.URL,
+		UploadExpiresAt: p.ExpiresAt,
+	}
+}
@@ -0,0 +1,48 @@
+package dto
+
+import (
+	"billing-engine/internal/domain/document"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateDocumentRequestValidate(t *testing.T) {
+	valid := CreateDocumentRequest{Type: "contract", FileName: "contract.pdf", ContentType: "application/pdf", SizeBytes: 1024}
+	assert.NoError(t, valid.Validate())
+
+	for name, tt := range map[string]struct {
+		mutate func(*CreateDocumentRequest)
+		want   string
+	}{
+		"unknown type":  {func(r *CreateDocumentRequest) { r.Type = "PASSPORT" }, `unknown document type "PASSPORT"`},
+		"no file name":  {func(r *CreateDocumentRequest) { r.FileName = " " }, "fileName cannot be empty"},
+		"no media type": {func(r *CreateDocumentRequest) { r.ContentType = "" }, "contentType cannot be empty"},
+		"no size":       {func(r *CreateDocumentRequest) { r.SizeBytes = 0 }, "sizeBytes must be a positive number"},
+		"negative size": {func(r *CreateDocumentRequest) { r.SizeBytes = -1 }, "sizeBytes must be a positive number"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := valid
+			tt.mutate(&req)
+			assert.EqualError(t, req.Validate(), tt.want)
+		})
+	}
+}
+
+func TestNewDocumentUploadResponse(t *testing.T) {
+	expires := time.Date(2025, 3, 3, 10, 15, 0, 0, time.UTC)
+	resp := NewDocumentUploadResponse(&document.PendingUpload{
+		Document: document.Document{ID: 9, Subject: document.Subject{Type: document.SubjectCustomer, ID: 5}, Type: document.TypeIDCopy,
+			Status: document.StatusPending, FileName: "ktp.png", ContentType: "image/png", SizeBytes: 2048, StorageKey: "customers/5/documents/k.png"},
+		URL:       "https://storage.example/upload",
+		ExpiresAt: expires,
+	})
+
+	assert.Equal(t, "9", resp.Document.ID)
+	assert.Equal(t, "customer", resp.Document.SubjectType)
+	assert.Equal(t, "5", resp.Document.SubjectID)
+	assert.Equal(t, "PENDING", resp.Document.Status)
+	assert.Equal(t, "https://storage.example/upload", resp.UploadURL)
+	assert.Equal(t, expires, resp.UploadExpiresAt)
+}
@@ -0,0 +1,93 @@
+package dto
+
+import (
+	"billing-engine/internal/event"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ReplayEventsRequest selects the events to publish again. At least one of
+// entityId, types, since, until or unpublishedOnly must be set.
+type ReplayEventsRequest struct {
+	// EntityID is the customer the events are about.
+	EntityID *int64   `json:"entityId,omitempty"`
+	Types    []string `json:"types,omitempty"`
+	// Since is inclusive and Until exclusive, both RFC 3339.
+	Since           *time.Time `json:"since,omitempty"`
+	Until           *time.Time `json:"until,omitempty"`
+	UnpublishedOnly bool       `json:"unpublishedOnly,omitempty"`
+	Limit           int        `json:"limit,omitempty"`
+}
+
+func (r *ReplayEventsRequest) Validate() error {
+	if r.EntityID != nil && *r.EntityID <= 0 {
+		return fmt.Errorf("entityId must be positive")
+	}
+	if r.Limit < 0 || r.Limit > event.MaxReplayEvents {
+		return fmt.Errorf("limit must be between 1 and %d", event.MaxReplayEvents)
+	}
+	return nil
+}
+
+func (r *ReplayEventsRequest) Filter() event.ReplayFilter {
+	return event.ReplayFilter{
+		EntityID:        r.EntityID,
+		Types:           r.Types,
+		Since:           r.Since,
+		Until:           r.Until,
+		UnpublishedOnly: r.UnpublishedOnly,
+		Limit:           r.Limit,
+	}
+}
+
+type EventRecordResponse struct {
+	ID             string          `json:"id"`
+	EventID        string          `json:"eventId"`
+	Type           string          `json:"type"`
+	EntityID       string          `json:"entityId"`
+	Sequence       int64           `json:"sequence,omitempty"`
+	OccurredAt     time.Time       `json:"occurredAt"`
+	PublishedAt    *time.Time      `json:"publishedAt,omitempty"`
+	ReplayCount    int             `json:"replayCount"`
+	LastReplayedAt *time.Time      `json:"lastReplayedAt,omitempty"`
+	Payload        json.RawMessage `json:"payload"`
+}
+
+func NewEventRecordListResponse(records []event.Record) []EventRecordResponse {
+	resp := make([]EventRecordResponse, 0, len(records))
+	for _, r := range records {
+		resp = append(resp, EventRecordResponse{
+			ID:             strconv.FormatInt(r.ID, 10),
+			EventID:        r.EventID,
+			Type:           r.Type,
+			EntityID:       strconv.FormatInt(r.EntityID, 10),
+			Sequence:       r.Sequence,
+			OccurredAt:     r.OccurredAt,
+			PublishedAt:    r.PublishedAt,
+			ReplayCount:    r.ReplayCount,
+			LastReplayedAt: r.LastReplayedAt,
+			Payload:        r.Payload,
+		})
+	}
+	return resp
+}
+
+// ReplayReportResponse lists in failed the event IDs the broker refused.
+type ReplayReportResponse struct {
+	Matched  int      `json:"matched"`
+	Replayed int      `json:"replayed"`
+	Failed   []string `json:"failed"`
+}
+
+func NewReplayReportResponse(r *event.ReplayReport) ReplayReportResponse {
+	if r == nil {
+		return ReplayReportResponse{Failed: []string{}}
+	}
+	failed := r.Failed
+	if failed == nil {
+		failed = []string{}
+	}
+	return ReplayReportResponse{Matched: r.Matched, Replayed: r.Replayed, Failed: failed}
+}
@@ -0,0 +1,36 @@
+package dto
+
+import (
+	"billing-engine/internal/event"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplayEventsRequestValidate(t *testing.T) {
+	entity := int64(7)
+	zero := int64(0)
+	assert.NoError(t, (&ReplayEventsRequest{EntityID: &entity}).Validate())
+	assert.Error(t, (&ReplayEventsRequest{EntityID: &zero}).Validate())
+	assert.Error(t, (&ReplayEventsRequest{Limit: event.MaxReplayEvents + 1}).Validate())
+}
+
+func TestNewEventRecordListResponse(t *testing.T) {
+	occurred := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	resp := NewEventRecordListResponse([]event.Record{{
+		ID: 3, EventID: "e-1", Type: event.TypeCustomerCreated, EntityID: 7, Sequence: 4,
+		Payload: json.RawMessage(`{"eventId":"e-1"}`), OccurredAt: occurred, ReplayCount: 2,
+	}})
+
+	body, err := json.Marshal(resp)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"id":"3","eventId":"e-1","type":"customer.created","entityId":"7","sequence":4,"occurredAt":"2025-03-01T08:00:00Z",
+		"replayCount":2,"payload":{"eventId":"e-1"}}]`, string(body))
+	assert.Empty(t, NewEventRecordListResponse(nil))
+}
+
+func TestNewReplayReportResponse(t *testing.T) {
+	assert.Equal(t, ReplayReportResponse{Matched: 2, Replayed: 2, Failed: []string{}}, NewReplayReportResponse(&event.ReplayReport{Matched: 2, Replayed: 2}))
+}
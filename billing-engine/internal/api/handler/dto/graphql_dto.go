@@ -0,0 +1,29 @@
+package dto
+
+import (
+	"fmt"
+	"strings"
+)
+
+type GraphQLRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+func (r *GraphQLRequest) Validate() error {
+	if strings.TrimSpace(r.Query) == "" {
+		return fmt.Errorf("query cannot be empty")
+	}
+	return nil
+}
+
+type GraphQLError struct {
+	Message string `json:"message"`
+	Path    []any  `json:"path,omitempty"`
+}
+
+type GraphQLResponse struct {
+	Data   any            `json:"data"`
+	Errors []GraphQLError `json:"errors,omitempty"`
+}
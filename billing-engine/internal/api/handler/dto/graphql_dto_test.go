@@ -0,0 +1,12 @@
+package dto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGraphQLRequestValidate(t *testing.T) {
+	assert.NoError(t, (&GraphQLRequest{Query: "{ customers { name } }"}).Validate())
+	assert.Error(t, (&GraphQLRequest{Query: "  "}).Validate())
+}
@@ -0,0 +1,35 @@
+package dto
+
+import (
+	"billing-engine/internal/domain/integrity"
+	"strconv"
+	"time"
+)
+
+// IntegrityFindingResponse is a violation the last integrity run found.
+// EntityType says whether EntityID is a customer or a loan.
+type IntegrityFindingResponse struct {
+	ID          string    `json:"id"`
+	Check       string    `json:"check"`
+	EntityType  string    `json:"entityType"`
+	EntityID    string    `json:"entityId"`
+	Detail      string    `json:"detail"`
+	FirstSeenAt time.Time `json:"firstSeenAt"`
+	LastSeenAt  time.Time `json:"lastSeenAt"`
+}
+
+func NewIntegrityFindingListResponse(findings []integrity.Finding) []IntegrityFindingResponse {
+	resp := make([]IntegrityFindingResponse, 0, len(findings))
+	for _, f := range findings {
+		resp = append(resp, IntegrityFindingResponse{
+			ID:          strconv.FormatInt(f.ID, 10),
+			Check:       string(f.Check),
+			EntityType:  f.EntityType,
+			EntityID:    strconv.FormatInt(f.EntityID, 10),
+			Detail:      f.Detail,
+			FirstSeenAt: f.FirstSeenAt,
+			LastSeenAt:  f.LastSeenAt,
+		})
+	}
+	return resp
+}
@@ -0,0 +1,100 @@
+package dto
+
+import (
+	"billing-engine/internal/jobs"
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// JobResponse is the state of an async job. Result holds the response the
+// endpoint would have given synchronously and is only set once the job
+// succeeded; Error is set once it failed. Attempts is above one when the job
+// was taken over from an instance that stopped while running it.
+type JobResponse struct {
+	ID         string          `json:"id"`
+	Kind       string          `json:"kind"`
+	Status     string          `json:"status"`
+	Total      int             `json:"total"`
+	Processed  int             `json:"processed"`
+	Attempts   int             `json:"attempts"`
+	Result     json.RawMessage `json:"result,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	CreatedAt  time.Time       `json:"createdAt"`
+	StartedAt  *time.Time      `json:"startedAt"`
+	FinishedAt *time.Time      `json:"finishedAt"`
+}
+
+func NewJobResponse(job jobs.Job) JobResponse {
+	return JobResponse{
+		ID:         job.ID,
+		Kind:       job.Kind,
+		Status:     string(job.Status),
+		Total:      job.Total,
+		Processed:  job.Processed,
+		Attempts:   job.Attempts,
+		Result:     job.Result,
+		Error:      job.Error,
+		CreatedAt:  job.CreatedAt,
+		StartedAt:  job.StartedAt,
+		FinishedAt: job.FinishedAt,
+	}
+}
+
+func NewJobListResponse(found []jobs.Job) []JobResponse {
+	resp := make([]JobResponse, 0, len(found))
+	for _, job := range found {
+		resp = append(resp, NewJobResponse(job))
+	}
+	return resp
+}
+
+// JobRunResponse is one run of a scheduled batch job. Processed includes
+// the Failed items, whose first errors are in ErrorSamples; Total is zero
+// when the job does not count its items ahead. DurationSeconds runs on while
+// the run is RUNNING.
+type JobRunResponse struct {
+	ID              string     `json:"id"`
+	Name            string     `json:"name"`
+	JobID           string     `json:"jobId,omitempty"`
+	Status          string     `json:"status"`
+	Total           int        `json:"total"`
+	Processed       int        `json:"processed"`
+	Failed          int        `json:"failed"`
+	ErrorSamples    []string   `json:"errorSamples"`
+	Error           string     `json:"error,omitempty"`
+	StartedAt       time.Time  `json:"startedAt"`
+	UpdatedAt       time.Time  `json:"updatedAt"`
+	FinishedAt      *time.Time `json:"finishedAt"`
+	DurationSeconds float64    `json:"durationSeconds"`
+}
+
+func NewJobRunResponse(run jobs.Run, now time.Time) JobRunResponse {
+	samples := run.ErrorSamples
+	if samples == nil {
+		samples = []string{}
+	}
+	return JobRunResponse{
+		ID:              strconv.FormatInt(run.ID, 10),
+		Name:            run.Name,
+		JobID:           run.JobID,
+		Status:          string(run.Status),
+		Total:           run.Total,
+		Processed:       run.Processed,
+		Failed:          run.Failed,
+		ErrorSamples:    samples,
+		Error:           run.Error,
+		StartedAt:       run.StartedAt,
+		UpdatedAt:       run.UpdatedAt,
+		FinishedAt:      run.FinishedAt,
+		DurationSeconds: run.Duration(now).Seconds(),
+	}
+}
+
+func NewJobRunListResponse(runs []jobs.Run, now time.Time) []JobRunResponse {
+	resp := make([]JobRunResponse, 0, len(runs))
+	for _, run := range runs {
+		resp = append(resp, NewJobRunResponse(run, now))
+	}
+	return resp
+}
@@ -0,0 +1,94 @@
+package dto
+
+import (
+	"billing-engine/internal/domain/loan"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ApplyAdjustmentRequest grants a payment holiday (PAYMENT_HOLIDAY) or a
+// zero-interest window (ZERO_INTEREST) of weeks weeks from startsOn. The
+// loan service checks the adjustment against the loan.
+type ApplyAdjustmentRequest struct {
+	Kind     string `json:"kind"`
+	StartsOn string `json:"startsOn"`
+	Weeks    int    `json:"weeks"`
+	Reason   string `json:"reason"`
+}
+
+func (r *ApplyAdjustmentRequest) Validate() error {
+	switch loan.AdjustmentKind(r.Kind) {
+	case loan.AdjustmentPaymentHoliday, loan.AdjustmentZeroInterest:
+	default:
+		return fmt.Errorf("kind must be %s or %s", loan.AdjustmentPaymentHoliday, loan.AdjustmentZeroInterest)
+	}
+	if _, err := time.Parse(time.DateOnly, r.StartsOn); err != nil {
+		return fmt.Errorf("invalid startsOn format, use YYYY-MM-DD")
+	}
+	if r.Weeks < 1 {
+		return fmt.Errorf("weeks must be positive")
+	}
+	if strings.TrimSpace(r.Reason) == "" {
+		return fmt.Errorf("reason cannot be empty")
+	}
+	return nil
+}
+
+// Adjustment returns the requested adjustment. Call it after Validate.
+func (r *ApplyAdjustmentRequest) Adjustment() loan.ScheduleAdjustment {
+	startsOn, _ := time.Parse(time.DateOnly, r.StartsOn)
+	return loan.ScheduleAdjustment{Kind: loan.AdjustmentKind(r.Kind), StartsOn: startsOn, Weeks: r.Weeks, Reason: r.Reason}
+}
+
+// ScheduleAdjustmentResponse is a payment holiday or zero-interest window.
+// Removed adjustments carry removedAt; appliedBy and removedBy are only
+// shown to staff.
+type ScheduleAdjustmentResponse struct {
+	ID        string     `json:"id"`
+	Kind      string     `json:"kind"`
+	StartsOn  string     `json:"startsOn"`
+	Weeks     int        `json:"weeks"`
+	Reason    string     `json:"reason"`
+	AppliedBy *string    `json:"appliedBy,omitempty"`
+	AppliedAt time.Time  `json:"appliedAt"`
+	RemovedBy *string    `json:"removedBy,omitempty"`
+	RemovedAt *time.Time `json:"removedAt,omitempty"`
+}
+
+func NewScheduleAdjustmentResponse(a *loan.ScheduleAdjustment) ScheduleAdjustmentResponse {
+	return ScheduleAdjustmentResponse{
+		ID:        strconv.FormatInt(a.ID, 10),
+		Kind:      string(a.Kind),
+		StartsOn:  a.StartsOn.Format(time.DateOnly),
+		Weeks:     a.Weeks,
+		Reason:    a.Reason,
+		AppliedBy: a.AppliedBy,
+		AppliedAt: a.AppliedAt,
+		RemovedBy: a.RemovedBy,
+		RemovedAt: a.RemovedAt,
+	}
+}
+
+// AdjustmentPlanResponse is an adjustment that was applied or removed, the
+// loan total it leads to and the installments it moved or recalculated.
+type AdjustmentPlanResponse struct {
+	LoanID          string                     `json:"loanId"`
+	Adjustment      ScheduleAdjustmentResponse `json:"adjustment"`
+	TotalLoanAmount string                     `json:"totalLoanAmount"`
+	Changes         []ScheduleChangeResponse   `json:"changes"`
+}
+
+func NewAdjustmentPlanResponse(p *loan.AdjustmentPlan) AdjustmentPlanResponse {
+	resp := AdjustmentPlanResponse{
+		LoanID:          strconv.FormatInt(p.Adjustment.LoanID, 10),
+		Adjustment:      NewScheduleAdjustmentResponse(&p.Adjustment),
+		TotalLoanAmount: formatMoney(p.TotalLoanAmount),
+		Changes:         make([]ScheduleChangeResponse, len(p.Changes)),
+	}
+	for i, change := range p.Changes {
+		resp.Changes[i] = newScheduleChangeResponse(change)
+	}
+	return resp
+}
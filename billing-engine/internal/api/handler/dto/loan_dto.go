@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 )
 
@@ -15,6 +16,11 @@ type CreateLoanRequest struct {
 	TermWeeks          int     `json:"termWeeks"`
 	AnnualInterestRate float64 `json:"annualInterestRate"`
 	StartDate          string  `json:"startDate"`
+	ExternalRef        string  `json:"externalRef,omitempty"`
+	PublicID           string  `json:"publicId,omitempty"`
+	// Product is the configured loan product whose schedule the loan is
+	// booked with; without one the schedule is standard.
+	Product string `json:"product,omitempty"`
 }
 
 func (r *CreateLoanRequest) Validate() error {
@@ -30,11 +36,31 @@ func (r *CreateLoanRequest) Validate() error {
 	if _, err := time.Parse(time.RFC3339[:10], r.StartDate); err != nil || r.StartDate == "" {
 		return fmt.Errorf("invalid startDate format (use YYYY-MM-DD): %w", err)
 	}
+	if err := validateExternalRef(r.ExternalRef); err != nil {
+		return err
+	}
+	if _, err := parsePublicID(r.PublicID); err != nil {
+		return err
+	}
 	return nil
 }
 
+// PublicUUID returns the client-chosen public ID, or uuid.Nil when the server
+// should generate one. Call it after Validate.
+func (r *CreateLoanRequest) PublicUUID() uuid.UUID {
+	id, _ := parsePublicID(r.PublicID)
+	return id
+}
+
 type MakePaymentRequest struct {
 	Amount string `json:"amount"`
+	// Channel is CASH, BANK_TRANSFER, GATEWAY or DIRECT_DEBIT. Payments
+	// without one are reported as UNSPECIFIED.
+	Channel string `json:"channel,omitempty"`
+	// Reference is the channel's identifier of the payment. A reference is
+	// accepted once per channel.
+	Reference   string `json:"reference,omitempty"`
+	CollectorID string `json:"collectorId,omitempty"`
 }
 
 func (r *MakePaymentRequest) Validate() error {
@@ -44,18 +70,53 @@ func (r *MakePaymentRequest) Validate() error {
 	return nil
 }
 
+// Details is validated by the loan service.
+func (r *MakePaymentRequest) Details() loan.PaymentDetails {
+	return loan.PaymentDetails{Channel: loan.PaymentChannel(r.Channel), Reference: r.Reference, CollectorID: r.CollectorID}
+}
+
 type LoanResponse struct {
-	ID                  string                  `json:"id"`
-	PrincipalAmount     string                  `json:"principalAmount"`
-	InterestRate        string                  `json:"interestRate"`
-	TermWeeks           int                     `json:"termWeeks"`
-	WeeklyPaymentAmount string                  `json:"weeklyPaymentAmount"`
-	TotalLoanAmount     string                  `json:"totalLoanAmount"`
-	StartDate           string                  `json:"startDate"`
-	Status              string                  `json:"status"`
-	CreatedAt           time.Time               `json:"createdAt"`
-	UpdatedAt           time.Time               `json:"updatedAt"`
-	Schedule            []ScheduleEntryResponse `json:"schedule,omitempty"`
+	ID                  string `json:"id"`
+	PublicID            string `json:"publicId,omitempty"`
+	PrincipalAmount     string `json:"principalAmount"`
+	InterestRate        string `json:"interestRate"`
+	TermWeeks           int    `json:"termWeeks"`
+	WeeklyPaymentAmount string `json:"weeklyPaymentAmount"`
+	TotalLoanAmount     string `json:"totalLoanAmount"`
+	StartDate           string `json:"startDate"`
+	Status              string `json:"status"`
+	DaysPastDue         int    `json:"daysPastDue"`
+	// ScheduleType is STANDARD, INTEREST_ONLY or BALLOON. An interest-only
+	// loan's first interestOnlyWeeks installments repay no principal, and
+	// weeklyPaymentAmount is the installment after them; a balloon loan's
+	// last installment repays balloonAmount of principal on top of the
+	// weeklyPaymentAmount.
+	ScheduleType      string `json:"scheduleType"`
+	InterestOnlyWeeks int    `json:"interestOnlyWeeks,omitempty"`
+	BalloonAmount     string `json:"balloonAmount,omitempty"`
+	// NextDueDate and NextDueAmount are the oldest unpaid installment's due
+	// date and what is left to pay on it, left out once every installment
+	// is paid.
+	NextDueDate           string                  `json:"nextDueDate,omitempty"`
+	NextDueAmount         string                  `json:"nextDueAmount,omitempty"`
+	InstallmentsPaid      int                     `json:"installmentsPaid"`
+	InstallmentsRemaining int                     `json:"installmentsRemaining"`
+	ExternalRef           *string                 `json:"externalRef,omitempty"`
+	CreatedAt             time.Time               `json:"createdAt"`
+	UpdatedAt             time.Time               `json:"updatedAt"`
+	Schedule              []ScheduleEntryResponse `json:"schedule,omitempty"`
+	// Hold is the open administrative hold; payments are refused while it
+	// is set.
+	Hold *LoanHoldResponse `json:"hold,omitempty"`
+	// RateHistory lists the loan's rate changes, oldest first; interestRate
+	// is the rate charged since the last one.
+	RateHistory []RateChangeResponse `json:"rateHistory,omitempty"`
+	// Adjustments lists the loan's payment holidays and zero-interest
+	// windows, including removed ones.
+	Adjustments []ScheduleAdjustmentResponse `json:"adjustments,omitempty"`
+	// Prepayments lists the lump sums paid against the principal, oldest
+	// first, with the terms each reamortized the loan to.
+	Prepayments []PrepaymentResponse `json:"prepayments,omitempty"`
 }
 
 type ScheduleEntryResponse struct {
@@ -69,8 +130,65 @@ type ScheduleEntryResponse struct {
 }
 
 type OutstandingResponse struct {
-	LoanID            string `json:"loanId"`
-	OutstandingAmount string `json:"outstandingAmount"`
+	LoanID            string                        `json:"loanId"`
+	OutstandingAmount string                        `json:"outstandingAmount"`
+	Breakdown         *OutstandingBreakdownResponse `json:"breakdown,omitempty"`
+}
+
+// OutstandingBreakdownResponse itemizes outstandingAmount. It equals
+// installments plus fees, penalties and tax, less suspense and credits.
+type OutstandingBreakdownResponse struct {
+	AsOf            time.Time             `json:"asOf"`
+	Installments    string                `json:"installments"`
+	Principal       string                `json:"principal"`
+	Interest        string                `json:"interest"`
+	AccruedInterest string                `json:"accruedInterest"`
+	PastDue         string                `json:"pastDue"`
+	Fees            string                `json:"fees"`
+	Penalties       string                `json:"penalties"`
+	Tax             string                `json:"tax"`
+	Suspense        string                `json:"suspense"`
+	Credits         string                `json:"credits"`
+	Items           []BalanceItemResponse `json:"items"`
+}
+
+type BalanceItemResponse struct {
+	// Kind is FEE, PENALTY, TAX, SUSPENSE or CREDIT.
+	Kind        string `json:"kind"`
+	Amount      string `json:"amount"`
+	Description string `json:"description,omitempty"`
+}
+
+func NewOutstandingResponse(b *loan.OutstandingBreakdown) OutstandingResponse {
+	if b == nil {
+		return OutstandingResponse{}
+	}
+	items := make([]BalanceItemResponse, 0, len(b.Items))
+	for _, item := range b.Items {
+		items = append(items, BalanceItemResponse{
+			Kind:        string(item.Kind),
+			Amount:      formatMoney(item.Amount),
+			Description: item.Description,
+		})
+	}
+	return OutstandingResponse{
+		LoanID:            strconv.FormatInt(b.LoanID, 10),
+		OutstandingAmount: formatMoney(b.Total),
+		Breakdown: &OutstandingBreakdownResponse{
+			AsOf:            b.AsOf,
+			Installments:    formatMoney(b.Installments),
+			Principal:       formatMoney(b.Principal),
+			Interest:        formatMoney(b.Interest),
+			AccruedInterest: formatMoney(b.AccruedInterest),
+			PastDue:         formatMoney(b.PastDue),
+			Fees:            formatMoney(b.Fees),
+			Penalties:       formatMoney(b.Penalties),
+			Tax:             formatMoney(b.Tax),
+			Suspense:        formatMoney(b.Suspense),
+			Credits:         formatMoney(b.Credits),
+			Items:           items,
+		},
+	}
 }
 
 type DelinquentResponse struct {
@@ -82,6 +200,13 @@ type ErrorDetail struct {
 	Code    string `json:"code,omitempty"`
 	Message string `json:"message"`
 	Field   string `json:"field,omitempty"`
+	// ExpectedAmount is set when a payment was rejected and holds the amount
+	// that settles the installment.
+	ExpectedAmount string `json:"expectedAmount,omitempty"`
+	// Details carries what the error is about, such as a conflicting ID.
+	Details map[string]string `json:"details,omitempty"`
+	// Retryable is set when the same request may succeed later.
+	Retryable bool `json:"retryable,omitempty"`
 }
 
 type ErrorResponse struct {
@@ -89,7 +214,10 @@ type ErrorResponse struct {
 }
 
 type TokenRequest struct {
-	Username string `json:"username"`
+	Username   string `json:"username"`
+	CustomerID int64  `json:"customerId,omitempty"`
+	// Admin requests the admin scope needed by the /admin routes.
+	Admin bool `json:"admin,omitempty"`
 }
 
 func NewLoanResponse(domainLoan *loan.Loan, includeSchedule bool) LoanResponse {
@@ -103,16 +231,58 @@ func NewLoanResponse(domainLoan *loan.Loan, includeSchedule bool) LoanResponse {
 	interestRateStr := decimal.NewFromFloat(domainLoan.InterestRate).String()
 
 	resp := LoanResponse{
-		ID:                  strconv.FormatInt(domainLoan.ID, 10),
-		PrincipalAmount:     principalStr,
-		InterestRate:        interestRateStr,
-		TermWeeks:           domainLoan.TermWeeks,
-		WeeklyPaymentAmount: weeklyPaymentStr,
-		TotalLoanAmount:     totalLoanStr,
-		StartDate:           domainLoan.StartDate.Format(time.RFC3339[:10]),
-		Status:              string(domainLoan.Status),
-		CreatedAt:           domainLoan.CreatedAt,
-		UpdatedAt:           domainLoan.UpdatedAt,
+		ID:                    strconv.FormatInt(domainLoan.ID, 10),
+		PublicID:              publicIDString(domainLoan.PublicID),
+		PrincipalAmount:       principalStr,
+		InterestRate:          interestRateStr,
+		TermWeeks:             domainLoan.TermWeeks,
+		WeeklyPaymentAmount:   weeklyPaymentStr,
+		TotalLoanAmount:       totalLoanStr,
+		StartDate:             domainLoan.StartDate.Format(time.RFC3339[:10]),
+		Status:                string(domainLoan.Status),
+		DaysPastDue:           domainLoan.DaysPastDue,
+		ScheduleType:          string(domainLoan.Structure.Kind()),
+		InterestOnlyWeeks:     domainLoan.Structure.InterestOnlyWeeks,
+		InstallmentsPaid:      domainLoan.InstallmentsPaid,
+		InstallmentsRemaining: domainLoan.InstallmentsRemaining,
+		ExternalRef:           domainLoan.ExternalRef,
+		CreatedAt:             domainLoan.CreatedAt,
+		UpdatedAt:             domainLoan.UpdatedAt,
+	}
+
+	if domainLoan.Structure.BalloonAmount > 0 {
+		resp.BalloonAmount = formatMoney(domainLoan.Structure.BalloonAmount)
+	}
+
+	if domainLoan.NextDueDate != nil {
+		resp.NextDueDate = domainLoan.NextDueDate.Format(time.DateOnly)
+		resp.NextDueAmount = formatMoney(domainLoan.NextDueAmount)
+	}
+
+	if domainLoan.Hold != nil {
+		hold := NewLoanHoldResponse(domainLoan.Hold)
+		resp.Hold = &hold
+	}
+
+	if len(domainLoan.RateHistory) > 0 {
+		resp.RateHistory = make([]RateChangeResponse, len(domainLoan.RateHistory))
+		for i := range domainLoan.RateHistory {
+			resp.RateHistory[i] = NewRateChangeResponse(&domainLoan.RateHistory[i])
+		}
+	}
+
+	if len(domainLoan.Adjustments) > 0 {
+		resp.Adjustments = make([]ScheduleAdjustmentResponse, len(domainLoan.Adjustments))
+		for i := range domainLoan.Adjustments {
+			resp.Adjustments[i] = NewScheduleAdjustmentResponse(&domainLoan.Adjustments[i])
+		}
+	}
+
+	if len(domainLoan.Prepayments) > 0 {
+		resp.Prepayments = make([]PrepaymentResponse, len(domainLoan.Prepayments))
+		for i := range domainLoan.Prepayments {
+			resp.Prepayments[i] = NewPrepaymentResponse(&domainLoan.Prepayments[i])
+		}
 	}
 
 	if includeSchedule && domainLoan.Schedule != nil {
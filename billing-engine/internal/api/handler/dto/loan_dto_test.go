@@ -5,7 +5,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewLoanResponse(t *testing.T) {
@@ -46,9 +48,22 @@ func TestNewLoanResponse(t *testing.T) {
 		assert.Equal(t, string(loan.StatusActive), response.Status)
 		assert.Equal(t, mockLoan.CreatedAt, response.CreatedAt)
 		assert.Equal(t, mockLoan.UpdatedAt, response.UpdatedAt)
+		assert.Equal(t, "STANDARD", response.ScheduleType)
+		assert.Empty(t, response.BalloonAmount)
 		assert.Nil(t, response.Schedule)
 	})
 
+	t.Run("Test with a balloon", func(t *testing.T) {
+		balloon := *mockLoan
+		balloon.Structure = loan.ScheduleStructure{Type: loan.ScheduleBalloon, BalloonAmount: 300}
+
+		response := NewLoanResponse(&balloon, false)
+
+		assert.Equal(t, "BALLOON", response.ScheduleType)
+		assert.Equal(t, "300.00", response.BalloonAmount)
+		assert.Zero(t, response.InterestOnlyWeeks)
+	})
+
 	t.Run("Test with schedule", func(t *testing.T) {
 		response := NewLoanResponse(mockLoan, true)
 
@@ -76,4 +91,250 @@ func TestNewLoanResponse(t *testing.T) {
 		assert.Nil(t, scheduleEntry.PaymentDate)
 		assert.Equal(t, string(loan.PaymentStatusPaid), scheduleEntry.Status)
 	})
+
+	t.Run("Test with hold", func(t *testing.T) {
+		assert.Nil(t, NewLoanResponse(mockLoan, false).Hold)
+
+		admin := "admin"
+		held := *mockLoan
+		held.Hold = &loan.Hold{ID: 3, LoanID: 1, Reason: "disputed", PlacedBy: &admin, PlacedAt: mockLoan.UpdatedAt}
+		response := NewLoanResponse(&held, false)
+
+		require.NotNil(t, response.Hold)
+		assert.Equal(t, "3", response.Hold.ID)
+		assert.Equal(t, "disputed", response.Hold.Reason)
+		assert.Equal(t, &admin, response.Hold.PlacedBy)
+		assert.Nil(t, response.Hold.ReleasedAt)
+	})
+
+	t.Run("Test with rate history", func(t *testing.T) {
+		assert.Nil(t, NewLoanResponse(mockLoan, false).RateHistory)
+
+		repriced := *mockLoan
+		repriced.RateHistory = []loan.RateChange{
+			{ID: 4, LoanID: 1, PreviousRate: 0.1, Rate: 0.125, EffectiveFrom: time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC), CreatedAt: mockLoan.UpdatedAt},
+		}
+		response := NewLoanResponse(&repriced, false)
+
+		require.Len(t, response.RateHistory, 1)
+		assert.Equal(t, "4", response.RateHistory[0].ID)
+		assert.Equal(t, "0.1", response.RateHistory[0].PreviousRate)
+		assert.Equal(t, "0.125", response.RateHistory[0].InterestRate)
+		assert.Equal(t, "2025-01-20", response.RateHistory[0].EffectiveFrom)
+		assert.Nil(t, response.RateHistory[0].ChangedBy)
+	})
+
+	t.Run("Test with schedule adjustments", func(t *testing.T) {
+		assert.Nil(t, NewLoanResponse(mockLoan, false).Adjustments)
+
+		adjusted := *mockLoan
+		removedAt := mockLoan.UpdatedAt
+		adjusted.Adjustments = []loan.ScheduleAdjustment{
+			{ID: 5, LoanID: 1, Kind: loan.AdjustmentPaymentHoliday, StartsOn: time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC), Weeks: 2, Reason: "leave", AppliedAt: mockLoan.UpdatedAt, RemovedAt: &removedAt},
+		}
+		response := NewLoanResponse(&adjusted, false)
+
+		require.Len(t, response.Adjustments, 1)
+		assert.Equal(t, "5", response.Adjustments[0].ID)
+		assert.Equal(t, "PAYMENT_HOLIDAY", response.Adjustments[0].Kind)
+		assert.Equal(t, "2025-01-20", response.Adjustments[0].StartsOn)
+		assert.Equal(t, 2, response.Adjustments[0].Weeks)
+		assert.Equal(t, &removedAt, response.Adjustments[0].RemovedAt)
+	})
+
+	t.Run("Test with prepayments", func(t *testing.T) {
+		assert.Nil(t, NewLoanResponse(mockLoan, false).Prepayments)
+
+		prepaid := *mockLoan
+		prepaid.Prepayments = []loan.Prepayment{
+			{ID: 6, LoanID: 1, Option: loan.PrepaymentReduceInstallment, Amount: 50, AfterWeek: 1, Principal: 150, WeeklyPrincipal: 75,
+				PreviousTermWeeks: 3, PreviousWeeklyPaymentAmount: 110, PreviousTotalLoanAmount: 330,
+				TermWeeks: 3, WeeklyPaymentAmount: 82.5, TotalLoanAmount: 325, Channel: loan.ChannelCash, PaidAt: mockLoan.UpdatedAt},
+		}
+		response := NewLoanResponse(&prepaid, false)
+
+		require.Len(t, response.Prepayments, 1)
+		p := response.Prepayments[0]
+		assert.Equal(t, "6", p.ID)
+		assert.Equal(t, "REDUCE_INSTALLMENT", p.Option)
+		assert.Equal(t, "50.00", p.Amount)
+		assert.Equal(t, "82.50", p.WeeklyPaymentAmount)
+		assert.Equal(t, "110.00", p.PreviousWeeklyPaymentAmount)
+		assert.Equal(t, "CASH", p.Channel)
+		assert.Nil(t, p.Reference)
+	})
+}
+
+func TestRepriceLoanRequestValidate(t *testing.T) {
+	req := RepriceLoanRequest{AnnualInterestRate: 0.12, EffectiveFrom: "2025-01-20"}
+	require.NoError(t, req.Validate())
+	assert.Equal(t, time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC), req.EffectiveDate())
+
+	assert.Error(t, (&RepriceLoanRequest{AnnualInterestRate: -0.1, EffectiveFrom: "2025-01-20"}).Validate())
+	assert.Error(t, (&RepriceLoanRequest{AnnualInterestRate: 0.12, EffectiveFrom: "20/01/2025"}).Validate())
+
+	negative := -0.1
+	assert.Error(t, (&RepriceLoansRequest{AnnualInterestRate: 0.12, EffectiveFrom: "2025-01-20", CurrentRate: &negative}).Validate())
+	assert.Error(t, (&RepriceLoansRequest{AnnualInterestRate: 0.12}).Validate())
+}
+
+func TestApplyAdjustmentRequestValidate(t *testing.T) {
+	req := ApplyAdjustmentRequest{Kind: "ZERO_INTEREST", StartsOn: "2025-01-20", Weeks: 4, Reason: "spring promotion"}
+	require.NoError(t, req.Validate())
+	assert.Equal(t, loan.ScheduleAdjustment{
+		Kind: loan.AdjustmentZeroInterest, StartsOn: time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC), Weeks: 4, Reason: "spring promotion",
+	}, req.Adjustment())
+
+	assert.Error(t, (&ApplyAdjustmentRequest{Kind: "DISCOUNT", StartsOn: "2025-01-20", Weeks: 4, Reason: "r"}).Validate())
+	assert.Error(t, (&ApplyAdjustmentRequest{Kind: "PAYMENT_HOLIDAY", StartsOn: "20/01/2025", Weeks: 4, Reason: "r"}).Validate())
+	assert.Error(t, (&ApplyAdjustmentRequest{Kind: "PAYMENT_HOLIDAY", StartsOn: "2025-01-20", Reason: "r"}).Validate())
+	assert.Error(t, (&ApplyAdjustmentRequest{Kind: "PAYMENT_HOLIDAY", StartsOn: "2025-01-20", Weeks: 4, Reason: " "}).Validate())
+}
+
+func TestNewAdjustmentPlanResponse(t *testing.T) {
+	before := loan.ScheduleEntry{ID: 2, LoanID: 1, WeekNumber: 2, DueDate: time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC), DueAmount: 110, Status: loan.PaymentStatusPending}
+	after := before
+	after.DueAmount = 100
+	resp := NewAdjustmentPlanResponse(&loan.AdjustmentPlan{
+		Adjustment:      loan.ScheduleAdjustment{ID: 5, LoanID: 1, Kind: loan.AdjustmentZeroInterest, StartsOn: before.DueDate, Weeks: 1, Reason: "promotion"},
+		Changes:         []loan.ScheduleChange{{Kind: loan.ScheduleEntryUpdated, WeekNumber: 2, Before: &before, After: &after}},
+		TotalLoanAmount: 320,
+	})
+
+	assert.Equal(t, "1", resp.LoanID)
+	assert.Equal(t, "5", resp.Adjustment.ID)
+	assert.Equal(t, "320.00", resp.TotalLoanAmount)
+	require.Len(t, resp.Changes, 1)
+	assert.Equal(t, 2, resp.Changes[0].WeekNumber)
+}
+
+func TestCreateLoanRequestPublicID(t *testing.T) {
+	req := CreateLoanRequest{Principal: 1000, TermWeeks: 10, AnnualInterestRate: 0.1, StartDate: "2025-01-01"}
+	assert.NoError(t, req.Validate())
+	assert.Equal(t, uuid.Nil, req.PublicUUID())
+
+	req.PublicID = "0b6bd0a4-93a7-4c55-8f3e-6d0f5f0f2a11"
+	assert.NoError(t, req.Validate())
+	assert.Equal(t, uuid.MustParse(req.PublicID), req.PublicUUID())
+
+	req.PublicID = "12345"
+	assert.Error(t, req.Validate())
+}
+
+func TestNewOutstandingResponse(t *testing.T) {
+	asOf := time.Date(2025, 2, 3, 0, 0, 0, 0, time.UTC)
+	resp := NewOutstandingResponse(&loan.OutstandingBreakdown{
+		LoanID:          7,
+		AsOf:            asOf,
+		Installments:    220,
+		Principal:       200,
+		Interest:        20,
+		AccruedInterest: 10,
+		PastDue:         110,
+		Fees:            5,
+		Suspense:        25,
+		Total:           200,
+		Items: []loan.BalanceItem{
+			{Kind: loan.BalanceFee, Amount: 5, Description: "late fee"},
+			{Kind: loan.BalanceSuspense, Amount: 25},
+		},
+	})
+
+	assert.Equal(t, "7", resp.LoanID)
+	assert.Equal(t, "200.00", resp.OutstandingAmount)
+	require.NotNil(t, resp.Breakdown)
+	assert.Equal(t, asOf, resp.Breakdown.AsOf)
+	assert.Equal(t, "220.00", resp.Breakdown.Installments)
+	assert.Equal(t, "20.00", resp.Breakdown.Interest)
+	assert.Equal(t, "110.00", resp.Breakdown.PastDue)
+	assert.Equal(t, "0.00", resp.Breakdown.Credits)
+	assert.Equal(t, "0.00", resp.Breakdown.Tax)
+	assert.Equal(t, []BalanceItemResponse{
+		{Kind: "FEE", Amount: "5.00", Description: "late fee"},
+		{Kind: "SUSPENSE", Amount: "25.00"},
+	}, resp.Breakdown.Items)
+
+	assert.Equal(t, OutstandingResponse{}, NewOutstandingResponse(nil))
+}
+
+func TestPrepaymentRequestValidate(t *testing.T) {
+	req := PrepaymentRequest{Amount: "100.00", Option: "REDUCE_TERM", Channel: "BANK_TRANSFER", Reference: "TRX-9"}
+	require.NoError(t, req.Validate())
+	assert.Equal(t, loan.PaymentDetails{Channel: loan.ChannelBankTransfer, Reference: "TRX-9"}, req.Details())
+
+	assert.Error(t, (&PrepaymentRequest{Option: "REDUCE_TERM"}).Validate())
+	assert.Error(t, (&PrepaymentRequest{Amount: "ten", Option: "REDUCE_TERM"}).Validate())
+	assert.Error(t, (&PrepaymentRequest{Amount: "100", Option: "SKIP"}).Validate())
+}
+
+func TestNewReamortizationResponse(t *testing.T) {
+	removed := loan.ScheduleEntry{ID: 3, LoanID: 1, WeekNumber: 3, DueDate: time.Date(2025, 1, 27, 0, 0, 0, 0, time.UTC), DueAmount: 110, Status: loan.PaymentStatusPending}
+	resp := NewReamortizationResponse(&loan.Reamortization{
+		Prepayment: loan.Prepayment{ID: 6, LoanID: 1, Option: loan.PrepaymentReduceTerm, Amount: 100, TermWeeks: 2, WeeklyPaymentAmount: 110, TotalLoanAmount: 320},
+		Changes:    []loan.ScheduleChange{{Kind: loan.ScheduleEntryRemoved, WeekNumber: 3, Before: &removed}},
+	})
+
+	assert.Equal(t, "1", resp.LoanID)
+	assert.Equal(t, "6", resp.Prepayment.ID)
+	assert.Equal(t, 2, resp.Prepayment.TermWeeks)
+	assert.Equal(t, "320.00", resp.Prepayment.TotalLoanAmount)
+	require.Len(t, resp.Changes, 1)
+	assert.Equal(t, "REMOVED", resp.Changes[0].Kind)
+	assert.Nil(t, resp.Changes[0].After)
+}
+
+func TestNewFeeResponse(t *testing.T) {
+	teller, supervisor := "teller", "supervisor"
+	decidedAt := time.Date(2025, 1, 15, 9, 0, 0, 0, time.UTC)
+	resp := NewFeeResponse(&loan.Fee{
+		ID: 4, LoanID: 1, Type: loan.FeeLegal, Amount: 50, Reason: "court filing", PostedBy: &teller,
+		Tax: &loan.TaxLine{ID: 7, FeeID: 4, LoanID: 1, Jurisdiction: "ID", Rate: 0.11, TaxableAmount: 50, Amount: 5.5},
+		Waivers: []loan.FeeWaiver{
+			{ID: 8, FeeID: 4, Reason: "duplicate", Status: loan.WaiverRejected, RequestedBy: &teller, DecidedBy: &supervisor, DecidedAt: &decidedAt},
+			{ID: 9, FeeID: 4, Reason: "filing withdrawn", Status: loan.WaiverApproved, RequestedBy: &teller, DecidedBy: &supervisor, DecidedAt: &decidedAt},
+		},
+	})
+
+	assert.Equal(t, "4", resp.ID)
+	assert.Equal(t, "LEGAL", resp.Type)
+	assert.Equal(t, "50.00", resp.Amount)
+	assert.Equal(t, &TaxLineResponse{Jurisdiction: "ID", Rate: "0.11", TaxableAmount: "50.00", Amount: "5.50"}, resp.Tax)
+	assert.True(t, resp.Waived)
+	require.Len(t, resp.Waivers, 2)
+	assert.Equal(t, "REJECTED", resp.Waivers[0].Status)
+	assert.Equal(t, "4", resp.Waivers[1].FeeID)
+	assert.Equal(t, &supervisor, resp.Waivers[1].DecidedBy)
+}
+
+func TestPostFeeRequestValidate(t *testing.T) {
+	assert.NoError(t, (&PostFeeRequest{Type: "BOUNCE", Amount: "25.00", Reason: "returned"}).Validate())
+	assert.Error(t, (&PostFeeRequest{Type: "BOUNCE", Amount: "", Reason: "returned"}).Validate())
+	assert.Error(t, (&PostFeeRequest{Type: "BOUNCE", Amount: "abc", Reason: "returned"}).Validate())
+	assert.Error(t, (&FeeWaiverRequest{Reason: "  "}).Validate())
+}
+
+func TestNewLoanSearchResponse(t *testing.T) {
+	publicID := uuid.New()
+	customerID, name, reference := int64(7), "Jane Doe", "TRF-1"
+	paidAt := time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC)
+
+	resp := NewLoanSearchResponse([]loan.SearchMatch{
+		{
+			LoanID: 3, LoanPublicID: publicID, Status: loan.StatusDelinquent, DaysPastDue: 21, CustomerID: &customerID, CustomerName: &name,
+			Payment: &loan.PaymentMatch{ID: 9, Amount: 110, Channel: loan.ChannelBankTransfer, Reference: &reference, PaidAt: paidAt, Prepayment: true},
+		},
+		{LoanID: 4, Status: loan.StatusPaidOff},
+	})
+
+	require.Len(t, resp, 2)
+	assert.Equal(t, "3", resp[0].LoanID)
+	assert.Equal(t, publicID.String(), resp[0].LoanPublicID)
+	assert.Equal(t, loan.DPDBucket(21), resp[0].Bucket)
+	assert.Equal(t, "7", *resp[0].CustomerID)
+	require.NotNil(t, resp[0].Payment)
+	assert.Equal(t, "110.00", resp[0].Payment.Amount)
+	assert.True(t, resp[0].Payment.Prepayment)
+	assert.Nil(t, resp[1].CustomerID)
+	assert.Nil(t, resp[1].Payment)
+	assert.NotNil(t, NewLoanSearchResponse(nil))
 }
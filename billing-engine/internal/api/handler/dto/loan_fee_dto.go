@@ -0,0 +1,140 @@
+package dto
+
+import (
+	"billing-engine/internal/domain/loan"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// FeeTypeResponse is an entry of the fee catalog.
+type FeeTypeResponse struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+func NewFeeTypesResponse(catalog []loan.FeeTypeInfo) []FeeTypeResponse {
+	resp := make([]FeeTypeResponse, len(catalog))
+	for i, info := range catalog {
+		resp[i] = FeeTypeResponse{Type: string(info.Type), Name: info.Name, Description: info.Description}
+	}
+	return resp
+}
+
+// PostFeeRequest charges a fee from the catalog. The type and reason are
+// checked by the loan service.
+type PostFeeRequest struct {
+	Type   string `json:"type"`
+	Amount string `json:"amount"`
+	Reason string `json:"reason"`
+}
+
+func (r *PostFeeRequest) Validate() error {
+	if _, err := decimal.NewFromString(r.Amount); err != nil || r.Amount == "" {
+		return fmt.Errorf("invalid fee amount: %w", err)
+	}
+	return nil
+}
+
+type FeeWaiverRequest struct {
+	Reason string `json:"reason"`
+}
+
+func (r *FeeWaiverRequest) Validate() error {
+	if strings.TrimSpace(r.Reason) == "" {
+		return fmt.Errorf("reason cannot be empty")
+	}
+	return nil
+}
+
+// FeeResponse is a fee posted to a loan. waived is true once a waiver of it
+// was approved and paidAt is set once a payment settled it; until then it
+// and its tax count towards the outstanding amount. tax is left out when the
+// fee was not taxed.
+type FeeResponse struct {
+	ID       string              `json:"id"`
+	LoanID   string              `json:"loanId"`
+	Type     string              `json:"type"`
+	Amount   string              `json:"amount"`
+	Reason   string              `json:"reason"`
+	PostedBy *string             `json:"postedBy,omitempty"`
+	PostedAt time.Time           `json:"postedAt"`
+	Tax      *TaxLineResponse    `json:"tax,omitempty"`
+	PaidAt   *time.Time          `json:"paidAt,omitempty"`
+	Waived   bool                `json:"waived"`
+	Waivers  []FeeWaiverResponse `json:"waivers"`
+}
+
+// TaxLineResponse is the tax charged on a fee, at the rate in force when it
+// was posted.
+type TaxLineResponse struct {
+	Jurisdiction  string `json:"jurisdiction"`
+	Rate          string `json:"rate"`
+	TaxableAmount string `json:"taxableAmount"`
+	Amount        string `json:"amount"`
+}
+
+// FeeWaiverResponse is a request to waive a fee. decidedBy and decidedAt are
+// set once it is approved or rejected.
+type FeeWaiverResponse struct {
+	ID          string     `json:"id"`
+	FeeID       string     `json:"feeId"`
+	Reason      string     `json:"reason"`
+	Status      string     `json:"status"`
+	RequestedBy *string    `json:"requestedBy,omitempty"`
+	RequestedAt time.Time  `json:"requestedAt"`
+	DecidedBy   *string    `json:"decidedBy,omitempty"`
+	DecidedAt   *time.Time `json:"decidedAt,omitempty"`
+}
+
+func NewFeeResponse(f *loan.Fee) FeeResponse {
+	resp := FeeResponse{
+		ID:       strconv.FormatInt(f.ID, 10),
+		LoanID:   strconv.FormatInt(f.LoanID, 10),
+		Type:     string(f.Type),
+		Amount:   formatMoney(f.Amount),
+		Reason:   f.Reason,
+		PostedBy: f.PostedBy,
+		PostedAt: f.PostedAt,
+		PaidAt:   f.PaidAt,
+		Waived:   f.Waived(),
+		Waivers:  make([]FeeWaiverResponse, len(f.Waivers)),
+	}
+	if t := f.Tax; t != nil {
+		resp.Tax = &TaxLineResponse{
+			Jurisdiction:  t.Jurisdiction,
+			Rate:          decimal.NewFromFloat(t.Rate).String(),
+			TaxableAmount: formatMoney(t.TaxableAmount),
+			Amount:        formatMoney(t.Amount),
+		}
+	}
+	for i := range f.Waivers {
+		resp.Waivers[i] = NewFeeWaiverResponse(&f.Waivers[i])
+	}
+	return resp
+}
+
+func NewFeesResponse(fees []loan.Fee) []FeeResponse {
+	resp := make([]FeeResponse, len(fees))
+	for i := range fees {
+		resp[i] = NewFeeResponse(&fees[i])
+	}
+	return resp
+}
+
+func NewFeeWaiverResponse(w *loan.FeeWaiver) FeeWaiverResponse {
+	return FeeWaiverResponse{
+		ID:          strconv.FormatInt(w.ID, 10),
+		FeeID:       strconv.FormatInt(w.FeeID, 10),
+		Reason:      w.Reason,
+		Status:      string(w.Status),
+		RequestedBy: w.RequestedBy,
+		RequestedAt: w.RequestedAt,
+		DecidedBy:   w.DecidedBy,
+		DecidedAt:   w.DecidedAt,
+	}
+}
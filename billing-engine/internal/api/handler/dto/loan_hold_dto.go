@@ -0,0 +1,44 @@
+package dto
+
+import (
+	"billing-engine/internal/domain/loan"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type PlaceHoldRequest struct {
+	Reason string `json:"reason"`
+}
+
+func (r *PlaceHoldRequest) Validate() error {
+	if strings.TrimSpace(r.Reason) == "" {
+		return fmt.Errorf("reason cannot be empty")
+	}
+	return nil
+}
+
+// LoanHoldResponse is an administrative hold. Released holds carry
+// releasedBy and releasedAt.
+type LoanHoldResponse struct {
+	ID         string     `json:"id"`
+	LoanID     string     `json:"loanId"`
+	Reason     string     `json:"reason"`
+	PlacedBy   *string    `json:"placedBy,omitempty"`
+	PlacedAt   time.Time  `json:"placedAt"`
+	ReleasedBy *string    `json:"releasedBy,omitempty"`
+	ReleasedAt *time.Time `json:"releasedAt,omitempty"`
+}
+
+func NewLoanHoldResponse(h *loan.Hold) LoanHoldResponse {
+	return LoanHoldResponse{
+		ID:         strconv.FormatInt(h.ID, 10),
+		LoanID:     strconv.FormatInt(h.LoanID, 10),
+		Reason:     h.Reason,
+		PlacedBy:   h.PlacedBy,
+		PlacedAt:   h.PlacedAt,
+		ReleasedBy: h.ReleasedBy,
+		ReleasedAt: h.ReleasedAt,
+	}
+}
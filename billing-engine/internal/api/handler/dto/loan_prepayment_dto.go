@@ -0,0 +1,99 @@
+package dto
+
+import (
+	"billing-engine/internal/domain/loan"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// PrepaymentRequest is a lump sum paid against the loan's principal. Option
+// is REDUCE_TERM to keep the installment and finish earlier, or
+// REDUCE_INSTALLMENT to keep the term and pay less each week. Channel and
+// reference are recorded as for a payment.
+type PrepaymentRequest struct {
+	Amount    string `json:"amount"`
+	Option    string `json:"option"`
+	Channel   string `json:"channel,omitempty"`
+	Reference string `json:"reference,omitempty"`
+}
+
+func (r *PrepaymentRequest) Validate() error {
+	if _, err := decimal.NewFromString(r.Amount); err != nil || r.Amount == "" {
+		return fmt.Errorf("invalid prepayment amount: %w", err)
+	}
+	switch loan.PrepaymentOption(r.Option) {
+	case loan.PrepaymentReduceTerm, loan.PrepaymentReduceInstallment:
+	default:
+		return fmt.Errorf("option must be %s or %s", loan.PrepaymentReduceTerm, loan.PrepaymentReduceInstallment)
+	}
+	return nil
+}
+
+// Details is validated by the loan service.
+func (r *PrepaymentRequest) Details() loan.PaymentDetails {
+	return loan.PaymentDetails{Channel: loan.PaymentChannel(r.Channel), Reference: r.Reference}
+}
+
+// PrepaymentResponse is a lump sum and the loan terms before and after it.
+// principal is what was left to repay after it; recordedBy is only shown to
+// staff.
+type PrepaymentResponse struct {
+	ID                          string    `json:"id"`
+	Option                      string    `json:"option"`
+	Amount                      string    `json:"amount"`
+	AfterWeek                   int       `json:"afterWeek"`
+	Principal                   string    `json:"principal"`
+	PreviousTermWeeks           int       `json:"previousTermWeeks"`
+	PreviousWeeklyPaymentAmount string    `json:"previousWeeklyPaymentAmount"`
+	PreviousTotalLoanAmount     string    `json:"previousTotalLoanAmount"`
+	TermWeeks                   int       `json:"termWeeks"`
+	WeeklyPaymentAmount         string    `json:"weeklyPaymentAmount"`
+	TotalLoanAmount             string    `json:"totalLoanAmount"`
+	Channel                     string    `json:"channel"`
+	Reference                   *string   `json:"reference,omitempty"`
+	RecordedBy                  *string   `json:"recordedBy,omitempty"`
+	PaidAt                      time.Time `json:"paidAt"`
+}
+
+func NewPrepaymentResponse(p *loan.Prepayment) PrepaymentResponse {
+	return PrepaymentResponse{
+		ID:                          strconv.FormatInt(p.ID, 10),
+		Option:                      string(p.Option),
+		Amount:                      formatMoney(p.Amount),
+		AfterWeek:                   p.AfterWeek,
+		Principal:                   formatMoney(p.Principal),
+		PreviousTermWeeks:           p.PreviousTermWeeks,
+		PreviousWeeklyPaymentAmount: formatMoney(p.PreviousWeeklyPaymentAmount),
+		PreviousTotalLoanAmount:     formatMoney(p.PreviousTotalLoanAmount),
+		TermWeeks:                   p.TermWeeks,
+		WeeklyPaymentAmount:         formatMoney(p.WeeklyPaymentAmount),
+		TotalLoanAmount:             formatMoney(p.TotalLoanAmount),
+		Channel:                     string(p.Channel),
+		Reference:                   p.Reference,
+		RecordedBy:                  p.RecordedBy,
+		PaidAt:                      p.PaidAt,
+	}
+}
+
+// ReamortizationResponse is a recorded prepayment and the installments it
+// recalculated, added or dropped.
+type ReamortizationResponse struct {
+	LoanID     string                   `json:"loanId"`
+	Prepayment PrepaymentResponse       `json:"prepayment"`
+	Changes    []ScheduleChangeResponse `json:"changes"`
+}
+
+func NewReamortizationResponse(r *loan.Reamortization) ReamortizationResponse {
+	resp := ReamortizationResponse{
+		LoanID:     strconv.FormatInt(r.Prepayment.LoanID, 10),
+		Prepayment: NewPrepaymentResponse(&r.Prepayment),
+		Changes:    make([]ScheduleChangeResponse, len(r.Changes)),
+	}
+	for i, change := range r.Changes {
+		resp.Changes[i] = newScheduleChangeResponse(change)
+	}
+	return resp
+}
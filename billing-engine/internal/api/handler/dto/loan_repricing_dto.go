@@ -0,0 +1,136 @@
+package dto
+
+import (
+	"billing-engine/internal/domain/loan"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// RepriceLoanRequest changes a loan's rate from effectiveFrom on. The rate is
+// given the way CreateLoanRequest takes it; the loan service checks its range.
+type RepriceLoanRequest struct {
+	AnnualInterestRate float64 `json:"annualInterestRate"`
+	EffectiveFrom      string  `json:"effectiveFrom"`
+}
+
+func (r *RepriceLoanRequest) Validate() error {
+	if r.AnnualInterestRate < 0 {
+		return fmt.Errorf("annualInterestRate cannot be negative")
+	}
+	if _, err := time.Parse(time.DateOnly, r.EffectiveFrom); err != nil {
+		return fmt.Errorf("invalid effectiveFrom format, use YYYY-MM-DD")
+	}
+	return nil
+}
+
+// EffectiveDate returns effectiveFrom. Call it after Validate.
+func (r *RepriceLoanRequest) EffectiveDate() time.Time {
+	d, _ := time.Parse(time.DateOnly, r.EffectiveFrom)
+	return d
+}
+
+// RepriceLoansRequest applies a base-rate change to many loans. Without
+// currentRate it reprices every loan that is not paid off.
+type RepriceLoansRequest struct {
+	AnnualInterestRate float64  `json:"annualInterestRate"`
+	EffectiveFrom      string   `json:"effectiveFrom"`
+	CurrentRate        *float64 `json:"currentRate,omitempty"`
+}
+
+func (r *RepriceLoansRequest) Validate() error {
+	single := RepriceLoanRequest{AnnualInterestRate: r.AnnualInterestRate, EffectiveFrom: r.EffectiveFrom}
+	if err := single.Validate(); err != nil {
+		return err
+	}
+	if r.CurrentRate != nil && *r.CurrentRate < 0 {
+		return fmt.Errorf("currentRate cannot be negative")
+	}
+	return nil
+}
+
+// EffectiveDate returns effectiveFrom. Call it after Validate.
+func (r *RepriceLoansRequest) EffectiveDate() time.Time {
+	d, _ := time.Parse(time.DateOnly, r.EffectiveFrom)
+	return d
+}
+
+func (r *RepriceLoansRequest) Filter() loan.RepricingFilter {
+	return loan.RepricingFilter{CurrentRate: r.CurrentRate}
+}
+
+// RateChangeResponse is one entry of a loan's rate history. changedBy is
+// only shown to staff.
+type RateChangeResponse struct {
+	ID            string    `json:"id"`
+	PreviousRate  string    `json:"previousRate"`
+	InterestRate  string    `json:"interestRate"`
+	EffectiveFrom string    `json:"effectiveFrom"`
+	ChangedBy     *string   `json:"changedBy,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+func NewRateChangeResponse(c *loan.RateChange) RateChangeResponse {
+	return RateChangeResponse{
+		ID:            strconv.FormatInt(c.ID, 10),
+		PreviousRate:  decimal.NewFromFloat(c.PreviousRate).String(),
+		InterestRate:  decimal.NewFromFloat(c.Rate).String(),
+		EffectiveFrom: c.EffectiveFrom.Format(time.DateOnly),
+		ChangedBy:     c.ChangedBy,
+		CreatedAt:     c.CreatedAt,
+	}
+}
+
+// RepricingResponse is a rate change, the loan terms it leads to and the
+// installments it recalculated.
+type RepricingResponse struct {
+	LoanID              string                   `json:"loanId"`
+	RateChange          RateChangeResponse       `json:"rateChange"`
+	WeeklyPaymentAmount string                   `json:"weeklyPaymentAmount"`
+	TotalLoanAmount     string                   `json:"totalLoanAmount"`
+	Changes             []ScheduleChangeResponse `json:"changes"`
+}
+
+func NewRepricingResponse(r *loan.Repricing) RepricingResponse {
+	resp := RepricingResponse{
+		LoanID:              strconv.FormatInt(r.Change.LoanID, 10),
+		RateChange:          NewRateChangeResponse(&r.Change),
+		WeeklyPaymentAmount: formatMoney(r.WeeklyPaymentAmount),
+		TotalLoanAmount:     formatMoney(r.TotalLoanAmount),
+		Changes:             make([]ScheduleChangeResponse, len(r.Changes)),
+	}
+	for i, change := range r.Changes {
+		resp.Changes[i] = newScheduleChangeResponse(change)
+	}
+	return resp
+}
+
+type RepricingSkipResponse struct {
+	LoanID string `json:"loanId"`
+	Reason string `json:"reason"`
+}
+
+// RepricingReportResponse sums up a batch repricing. Skipped lists the loans
+// that could not take the new rate, such as those already paid past
+// effectiveFrom.
+type RepricingReportResponse struct {
+	InterestRate  string                  `json:"interestRate"`
+	EffectiveFrom string                  `json:"effectiveFrom"`
+	Repriced      int                     `json:"repriced"`
+	Skipped       []RepricingSkipResponse `json:"skipped"`
+}
+
+func NewRepricingReportResponse(r *loan.RepricingReport) RepricingReportResponse {
+	resp := RepricingReportResponse{
+		InterestRate:  decimal.NewFromFloat(r.Rate).String(),
+		EffectiveFrom: r.EffectiveFrom.Format(time.DateOnly),
+		Repriced:      r.Repriced,
+		Skipped:       make([]RepricingSkipResponse, len(r.Skipped)),
+	}
+	for i, skip := range r.Skipped {
+		resp.Skipped[i] = RepricingSkipResponse{LoanID: strconv.FormatInt(skip.LoanID, 10), Reason: skip.Reason}
+	}
+	return resp
+}
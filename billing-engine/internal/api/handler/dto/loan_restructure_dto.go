@@ -0,0 +1,109 @@
+package dto
+
+import (
+	"billing-engine/internal/domain/loan"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// RestructurePreviewRequest is a change to a loan's terms to preview: a
+// REPRICING to annualInterestRate from effectiveFrom, a PAYMENT_HOLIDAY or
+// ZERO_INTEREST window of weeks weeks from startsOn, or a PREPAYMENT of
+// amount with option. Only the fields of the kind are read.
+type RestructurePreviewRequest struct {
+	Kind               string  `json:"kind"`
+	AnnualInterestRate float64 `json:"annualInterestRate,omitempty"`
+	EffectiveFrom      string  `json:"effectiveFrom,omitempty"`
+	StartsOn           string  `json:"startsOn,omitempty"`
+	Weeks              int     `json:"weeks,omitempty"`
+	Amount             string  `json:"amount,omitempty"`
+	Option             string  `json:"option,omitempty"`
+}
+
+func (r *RestructurePreviewRequest) Validate() error {
+	switch loan.RestructureKind(r.Kind) {
+	case loan.RestructureRepricing:
+		repricing := RepriceLoanRequest{AnnualInterestRate: r.AnnualInterestRate, EffectiveFrom: r.EffectiveFrom}
+		return repricing.Validate()
+	case loan.RestructurePaymentHoliday, loan.RestructureZeroInterest:
+		if _, err := time.Parse(time.DateOnly, r.StartsOn); err != nil {
+			return fmt.Errorf("invalid startsOn format, use YYYY-MM-DD")
+		}
+		if r.Weeks < 1 {
+			return fmt.Errorf("weeks must be positive")
+		}
+		return nil
+	case loan.RestructurePrepayment:
+		prepayment := PrepaymentRequest{Amount: r.Amount, Option: r.Option}
+		return prepayment.Validate()
+	}
+	return fmt.Errorf("kind must be %s, %s, %s or %s",
+		loan.RestructureRepricing, loan.RestructurePaymentHoliday, loan.RestructureZeroInterest, loan.RestructurePrepayment)
+}
+
+// Restructure returns the change to preview. Call it after Validate.
+func (r *RestructurePreviewRequest) Restructure() loan.Restructure {
+	restructure := loan.Restructure{Kind: loan.RestructureKind(r.Kind)}
+	switch restructure.Kind {
+	case loan.RestructureRepricing:
+		restructure.Rate = r.AnnualInterestRate
+		restructure.EffectiveFrom, _ = time.Parse(time.DateOnly, r.EffectiveFrom)
+	case loan.RestructurePaymentHoliday, loan.RestructureZeroInterest:
+		restructure.StartsOn, _ = time.Parse(time.DateOnly, r.StartsOn)
+		restructure.Weeks = r.Weeks
+	case loan.RestructurePrepayment:
+		restructure.Amount, _ = decimal.RequireFromString(r.Amount).Float64()
+		restructure.Option = loan.PrepaymentOption(r.Option)
+	}
+	return restructure
+}
+
+// LoanTermsResponse is what a loan costs and when its last installment is
+// due, before or after a restructure.
+type LoanTermsResponse struct {
+	InterestRate        string `json:"interestRate"`
+	TermWeeks           int    `json:"termWeeks"`
+	WeeklyPaymentAmount string `json:"weeklyPaymentAmount"`
+	TotalLoanAmount     string `json:"totalLoanAmount"`
+	TotalInterest       string `json:"totalInterest"`
+	EndDate             string `json:"endDate"`
+}
+
+func newLoanTermsResponse(t loan.LoanTerms) LoanTermsResponse {
+	return LoanTermsResponse{
+		InterestRate:        decimal.NewFromFloat(t.InterestRate).String(),
+		TermWeeks:           t.TermWeeks,
+		WeeklyPaymentAmount: formatMoney(t.WeeklyPaymentAmount),
+		TotalLoanAmount:     formatMoney(t.TotalLoanAmount),
+		TotalInterest:       formatMoney(t.TotalInterest),
+		EndDate:             t.EndDate.Format(time.DateOnly),
+	}
+}
+
+// RestructurePreviewResponse is the diff a restructure would make to the
+// schedule and the loan terms before and after it. Nothing has been
+// changed.
+type RestructurePreviewResponse struct {
+	LoanID  string                   `json:"loanId"`
+	Kind    string                   `json:"kind"`
+	Before  LoanTermsResponse        `json:"before"`
+	After   LoanTermsResponse        `json:"after"`
+	Changes []ScheduleChangeResponse `json:"changes"`
+}
+
+func NewRestructurePreviewResponse(p *loan.RestructurePreview) RestructurePreviewResponse {
+	resp := RestructurePreviewResponse{
+		LoanID:  strconv.FormatInt(p.LoanID, 10),
+		Kind:    string(p.Restructure.Kind),
+		Before:  newLoanTermsResponse(p.Before),
+		After:   newLoanTermsResponse(p.After),
+		Changes: make([]ScheduleChangeResponse, len(p.Changes)),
+	}
+	for i, change := range p.Changes {
+		resp.Changes[i] = newScheduleChangeResponse(change)
+	}
+	return resp
+}
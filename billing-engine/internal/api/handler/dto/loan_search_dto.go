@@ -0,0 +1,59 @@
+package dto
+
+import (
+	"billing-engine/internal/domain/loan"
+	"strconv"
+	"time"
+)
+
+// SearchPaymentResponse is the payment or prepayment a loan search matched.
+type SearchPaymentResponse struct {
+	ID         string    `json:"id"`
+	Amount     string    `json:"amount"`
+	Channel    string    `json:"channel"`
+	Reference  *string   `json:"reference,omitempty"`
+	PaidAt     time.Time `json:"paidAt"`
+	Prepayment bool      `json:"prepayment"`
+}
+
+// LoanSearchMatchResponse is a loan a search found, with the payment that
+// matched unless the search was by customer name alone.
+type LoanSearchMatchResponse struct {
+	LoanID       string                 `json:"loanId"`
+	LoanPublicID string                 `json:"loanPublicId,omitempty"`
+	Status       string                 `json:"status"`
+	DaysPastDue  int                    `json:"daysPastDue"`
+	Bucket       string                 `json:"bucket"`
+	CustomerID   *string                `json:"customerId,omitempty"`
+	CustomerName *string                `json:"customerName,omitempty"`
+	Payment      *SearchPaymentResponse `json:"payment,omitempty"`
+}
+
+func NewLoanSearchResponse(matches []loan.SearchMatch) []LoanSearchMatchResponse {
+	resp := make([]LoanSearchMatchResponse, len(matches))
+	for i, m := range matches {
+		resp[i] = LoanSearchMatchResponse{
+			LoanID:       strconv.FormatInt(m.LoanID, 10),
+			LoanPublicID: publicIDString(m.LoanPublicID),
+			Status:       string(m.Status),
+			DaysPastDue:  m.DaysPastDue,
+			Bucket:       loan.DPDBucket(m.DaysPastDue),
+			CustomerName: m.CustomerName,
+		}
+		if m.CustomerID != nil {
+			id := strconv.FormatInt(*m.CustomerID, 10)
+			resp[i].CustomerID = &id
+		}
+		if p := m.Payment; p != nil {
+			resp[i].Payment = &SearchPaymentResponse{
+				ID:         strconv.FormatInt(p.ID, 10),
+				Amount:     formatMoney(p.Amount),
+				Channel:    string(p.Channel),
+				Reference:  p.Reference,
+				PaidAt:     p.PaidAt,
+				Prepayment: p.Prepayment,
+			}
+		}
+	}
+	return resp
+}
@@ -0,0 +1,88 @@
+package dto
+
+import (
+	"billing-engine/internal/domain/note"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type CreateNoteRequest struct {
+	Body string `json:"body"`
+}
+
+func (r *CreateNoteRequest) Validate() error {
+	if strings.TrimSpace(r.Body) == "" {
+		return fmt.Errorf("body cannot be empty")
+	}
+	return nil
+}
+
+type NoteResponse struct {
+	ID          string    `json:"id"`
+	SubjectType string    `json:"subjectType"`
+	SubjectID   string    `json:"subjectId"`
+	Body        string    `json:"body"`
+	Author      string    `json:"author,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+func NewNoteResponse(n *note.Note) NoteResponse {
+	if n == nil {
+		return NoteResponse{}
+	}
+	return NoteResponse{
+		ID:          strconv.FormatInt(n.ID, 10),
+		SubjectType: string(n.Subject.Type),
+		SubjectID:   strconv.FormatInt(n.Subject.ID, 10),
+		Body:        n.Body,
+		Author:      n.Author,
+		CreatedAt:   n.CreatedAt,
+	}
+}
+
+func NewNoteListResponse(notes []note.Note) []NoteResponse {
+	resp := make([]NoteResponse, 0, len(notes))
+	for i := range notes {
+		resp = append(resp, NewNoteResponse(&notes[i]))
+	}
+	return resp
+}
+
+// AttachmentResponse describes a stored file. The storage key is internal
+// and not exposed.
+type AttachmentResponse struct {
+	ID          string    `json:"id"`
+	SubjectType string    `json:"subjectType"`
+	SubjectID   string    `json:"subjectId"`
+	FileName    string    `json:"fileName"`
+	ContentType string    `json:"contentType"`
+	SizeBytes   int64     `json:"sizeBytes"`
+	UploadedBy  string    `json:"uploadedBy,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+func NewAttachmentResponse(a *note.Attachment) AttachmentResponse {
+	if a == nil {
+		return AttachmentResponse{}
+	}
+	return AttachmentResponse{
+		ID:          strconv.FormatInt(a.ID, 10),
+		SubjectType: string(a.Subject.Type),
+		SubjectID:   strconv.FormatInt(a.Subject.ID, 10),
+		FileName:    a.FileName,
+		ContentType: a.ContentType,
+		SizeBytes:   a.SizeBytes,
+		UploadedBy:  a.UploadedBy,
+		CreatedAt:   a.CreatedAt,
+	}
+}
+
+func NewAttachmentListResponse(attachments []note.Attachment) []AttachmentResponse {
+	resp := make([]AttachmentResponse, 0, len(attachments))
+	for i := range attachments {
+		resp = append(resp, NewAttachmentResponse(&attachments[i]))
+	}
+	return resp
+}
@@ -0,0 +1,42 @@
+package dto
+
+import (
+	"billing-engine/internal/domain/note"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateNoteRequestValidate(t *testing.T) {
+	assert.NoError(t, (&CreateNoteRequest{Body: "Customer asked for a call back"}).Validate())
+	assert.EqualError(t, (&CreateNoteRequest{Body: "  "}).Validate(), "body cannot be empty")
+}
+
+func TestNewNoteListResponse(t *testing.T) {
+	now := time.Now()
+	resp := NewNoteListResponse([]note.Note{
+		{ID: 3, Subject: note.Subject{Type: note.SubjectLoan, ID: 42}, Body: "Promised to pay", Author: "ops", CreatedAt: now},
+	})
+
+	require.Len(t, resp, 1)
+	assert.Equal(t, "3", resp[0].ID)
+	assert.Equal(t, "loan", resp[0].SubjectType)
+	assert.Equal(t, "42", resp[0].SubjectID)
+	assert.Equal(t, "ops", resp[0].Author)
+	assert.NotNil(t, NewNoteListResponse(nil), "empty lists render as [] not null")
+}
+
+func TestNewAttachmentResponse(t *testing.T) {
+	resp := NewAttachmentResponse(&note.Attachment{
+		ID: 8, Subject: note.Subject{Type: note.SubjectCustomer, ID: 5},
+		FileName: "id-card.png", ContentType: "image/png", SizeBytes: 2048, StorageKey: "customers/5/k",
+	})
+
+	assert.Equal(t, "8", resp.ID)
+	assert.Equal(t, "customer", resp.SubjectType)
+	assert.Equal(t, "id-card.png", resp.FileName)
+	assert.Equal(t, int64(2048), resp.SizeBytes)
+	assert.Equal(t, AttachmentResponse{}, NewAttachmentResponse(nil))
+}
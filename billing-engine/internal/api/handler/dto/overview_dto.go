@@ -0,0 +1,117 @@
+package dto
+
+import (
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/domain/overview"
+	"strconv"
+	"time"
+)
+
+// OverviewLoanResponse is a loan of the customer that is not paid off.
+type OverviewLoanResponse struct {
+	Loan        LoanResponse `json:"loan"`
+	Outstanding string       `json:"outstanding"`
+	Bucket      string       `json:"bucket"`
+}
+
+// PaymentResponse is an entry of the payments ledger. It names the
+// installment it paid or, once every installment was paid, the fee.
+type PaymentResponse struct {
+	ID          string    `json:"id"`
+	LoanID      string    `json:"loanId"`
+	ScheduleID  *string   `json:"scheduleId,omitempty"`
+	FeeID       *string   `json:"feeId,omitempty"`
+	Amount      string    `json:"amount"`
+	Channel     string    `json:"channel"`
+	Reference   *string   `json:"reference,omitempty"`
+	CollectorID *string   `json:"collectorId,omitempty"`
+	PaidAt      time.Time `json:"paidAt"`
+}
+
+// OverviewCollectionsResponse is the open collections assignment of the
+// customer's loan with its actions, newest first.
+type OverviewCollectionsResponse struct {
+	Assignment CollectionAssignmentResponse `json:"assignment"`
+	Actions    []CollectionActionResponse   `json:"actions"`
+}
+
+// NotificationResponse is an entry of notify-service's delivery log.
+type NotificationResponse struct {
+	ID        string     `json:"id"`
+	Event     string     `json:"event"`
+	Channel   string     `json:"channel"`
+	Recipient string     `json:"recipient"`
+	Subject   string     `json:"subject,omitempty"`
+	Status    string     `json:"status"`
+	Error     string     `json:"error,omitempty"`
+	SentAt    *time.Time `json:"sentAt,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+type CustomerOverviewResponse struct {
+	Customer       CustomerResponse             `json:"customer"`
+	Loans          []OverviewLoanResponse       `json:"loans"`
+	RecentPayments []PaymentResponse            `json:"recentPayments"`
+	Collections    *OverviewCollectionsResponse `json:"collections,omitempty"`
+	// RecentNotifications is empty when NotificationsAvailable is false,
+	// because notify-service could not be asked.
+	RecentNotifications    []NotificationResponse `json:"recentNotifications"`
+	NotificationsAvailable bool                   `json:"notificationsAvailable"`
+}
+
+func NewPaymentResponse(p *loan.Payment) PaymentResponse {
+	return PaymentResponse{
+		ID:          strconv.FormatInt(p.ID, 10),
+		LoanID:      strconv.FormatInt(p.LoanID, 10),
+		ScheduleID:  formatOptionalID(p.ScheduleID),
+		FeeID:       formatOptionalID(p.FeeID),
+		Amount:      formatMoney(p.Amount),
+		Channel:     string(p.Channel),
+		Reference:   p.Reference,
+		CollectorID: p.CollectorID,
+		PaidAt:      p.PaidAt,
+	}
+}
+
+func NewCustomerOverviewResponse(o *overview.CustomerOverview) CustomerOverviewResponse {
+	if o == nil {
+		return CustomerOverviewResponse{}
+	}
+	resp := CustomerOverviewResponse{
+		Customer:               NewCustomerResponse(o.Customer),
+		Loans:                  make([]OverviewLoanResponse, len(o.Loans)),
+		RecentPayments:         make([]PaymentResponse, len(o.Payments)),
+		RecentNotifications:    make([]NotificationResponse, len(o.Notifications)),
+		NotificationsAvailable: o.NotificationsAvailable,
+	}
+	for i, l := range o.Loans {
+		resp.Loans[i] = OverviewLoanResponse{
+			Loan:        NewLoanResponse(l.Loan, false),
+			Outstanding: formatMoney(l.Outstanding),
+			Bucket:      loan.DPDBucket(l.Loan.DaysPastDue),
+		}
+	}
+	for i := range o.Payments {
+		resp.RecentPayments[i] = NewPaymentResponse(&o.Payments[i])
+	}
+	if o.Collections != nil {
+		resp.Collections = &OverviewCollectionsResponse{
+			Assignment: NewCollectionAssignmentResponse(&o.Collections.Assignment),
+			Actions:    NewCollectionActionListResponse(o.Collections.Actions),
+		}
+	}
+	for i, n := range o.Notifications {
+		resp.RecentNotifications[i] = NotificationResponse{
+			ID:        strconv.FormatInt(n.ID, 10),
+			Event:     n.Event,
+			Channel:   n.Channel,
+			Recipient: n.Recipient,
+			Subject:   n.Subject,
+			Status:    n.Status,
+			Error:     n.Error,
+			SentAt:    n.SentAt,
+			CreatedAt: n.CreatedAt,
+		}
+	}
+	return resp
+}
@@ -0,0 +1,65 @@
+package dto
+
+import (
+	"billing-engine/internal/domain/collections"
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/domain/overview"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCustomerOverviewResponse(t *testing.T) {
+	t.Run("includes every part", func(t *testing.T) {
+		loanID := int64(3)
+		reference := "TRF-1"
+		paidAt := time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC)
+		scheduleID := int64(12)
+
+		resp := NewCustomerOverviewResponse(&overview.CustomerOverview{
+			Customer: &customer.Customer{CustomerID: 7, Name: "Jane Doe", LoanID: &loanID},
+			Loans: []overview.LoanOverview{{
+				Loan:        &loan.Loan{ID: 3, Status: loan.StatusDelinquent, DaysPastDue: 21, StartDate: paidAt},
+				Outstanding: 440,
+			}},
+			Payments: []loan.Payment{{ID: 9, LoanID: 3, ScheduleID: &scheduleID, Amount: 110, Channel: loan.ChannelBankTransfer, Reference: &reference, PaidAt: paidAt}},
+			Collections: &overview.OpenCollections{
+				Assignment: collections.Assignment{ID: 4, LoanID: 3, Collector: "alice", Status: collections.AssignmentOpen, DaysPastDue: 21},
+				Actions:    []collections.Action{{ID: 5, AssignmentID: 4, LoanID: 3, Type: collections.ActionCall}},
+			},
+			Notifications:          []overview.Notification{{ID: 2, Event: "payment_reminder", Channel: "sms", Status: "SENT"}},
+			NotificationsAvailable: true,
+		})
+
+		assert.Equal(t, "Jane Doe", resp.Customer.Name)
+		require.Len(t, resp.Loans, 1)
+		assert.Equal(t, "3", resp.Loans[0].Loan.ID)
+		assert.Equal(t, 21, resp.Loans[0].Loan.DaysPastDue)
+		assert.Equal(t, "440.00", resp.Loans[0].Outstanding)
+		assert.Equal(t, loan.DPDBucket(21), resp.Loans[0].Bucket)
+		assert.Nil(t, resp.Loans[0].Loan.Schedule)
+		require.Len(t, resp.RecentPayments, 1)
+		assert.Equal(t, "110.00", resp.RecentPayments[0].Amount)
+		assert.Equal(t, "BANK_TRANSFER", resp.RecentPayments[0].Channel)
+		assert.Equal(t, "12", *resp.RecentPayments[0].ScheduleID)
+		assert.Nil(t, resp.RecentPayments[0].FeeID)
+		require.NotNil(t, resp.Collections)
+		assert.Equal(t, "alice", resp.Collections.Assignment.Collector)
+		assert.Equal(t, "CALL", resp.Collections.Actions[0].Type)
+		assert.Equal(t, "2", resp.RecentNotifications[0].ID)
+		assert.True(t, resp.NotificationsAvailable)
+	})
+
+	t.Run("keeps empty parts as empty lists", func(t *testing.T) {
+		resp := NewCustomerOverviewResponse(&overview.CustomerOverview{Customer: &customer.Customer{CustomerID: 7}})
+
+		assert.NotNil(t, resp.Loans)
+		assert.NotNil(t, resp.RecentPayments)
+		assert.NotNil(t, resp.RecentNotifications)
+		assert.Nil(t, resp.Collections)
+		assert.False(t, resp.NotificationsAvailable)
+	})
+}
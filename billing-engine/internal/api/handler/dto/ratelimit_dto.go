@@ -0,0 +1,41 @@
+package dto
+
+import (
+	"billing-engine/internal/ratelimit"
+	"time"
+)
+
+// RateLimitConsumerResponse is what the rate limiter counted for one client
+// since the client was last idle.
+type RateLimitConsumerResponse struct {
+	Principal string    `json:"principal" example:"203.0.113.7"`
+	Requests  uint64    `json:"requests"`
+	Allowed   uint64    `json:"allowed"`
+	Limited   uint64    `json:"limited"`
+	LastSeen  time.Time `json:"lastSeen"`
+}
+
+func NewRateLimitConsumerListResponse(consumers []ratelimit.Consumer) []RateLimitConsumerResponse {
+	resp := make([]RateLimitConsumerResponse, len(consumers))
+	for i, c := range consumers {
+		resp[i] = RateLimitConsumerResponse{
+			Principal: c.Principal,
+			Requests:  c.Requests(),
+			Allowed:   c.Allowed,
+			Limited:   c.Limited,
+			LastSeen:  c.LastSeen,
+		}
+	}
+	return resp
+}
+
+// RateLimitListsResponse holds the client IPs that are refused outright and
+// those that skip the rate limit.
+type RateLimitListsResponse struct {
+	Blocklist []string `json:"blocklist"`
+	Allowlist []string `json:"allowlist"`
+}
+
+func NewRateLimitListsResponse(blocked, allowed []string) RateLimitListsResponse {
+	return RateLimitListsResponse{Blocklist: blocked, Allowlist: allowed}
+}
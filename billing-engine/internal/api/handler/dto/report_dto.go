@@ -0,0 +1,180 @@
+package dto
+
+import (
+	"billing-engine/internal/domain/loan"
+	"strconv"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type LoanSnapshotResponse struct {
+	Date        string `json:"date"`
+	Status      string `json:"status"`
+	Outstanding string `json:"outstanding"`
+	DPD         int    `json:"dpd"`
+}
+
+type LoanHistoryResponse struct {
+	LoanID    string                 `json:"loanId"`
+	From      string                 `json:"from"`
+	To        string                 `json:"to"`
+	Snapshots []LoanSnapshotResponse `json:"snapshots"`
+}
+
+// PortfolioLoanResponse is one line of the streamed portfolio, the snapshot
+// of a single loan on Date.
+type PortfolioLoanResponse struct {
+	LoanID      string `json:"loanId"`
+	Date        string `json:"date"`
+	Status      string `json:"status"`
+	Outstanding string `json:"outstanding"`
+	DPD         int    `json:"dpd"`
+}
+
+func NewPortfolioLoanResponse(s loan.Snapshot) PortfolioLoanResponse {
+	return PortfolioLoanResponse{
+		LoanID:      strconv.FormatInt(s.LoanID, 10),
+		Date:        s.Date.Format(time.DateOnly),
+		Status:      string(s.Status),
+		Outstanding: formatMoney(s.Outstanding),
+		DPD:         s.DPD,
+	}
+}
+
+type PortfolioBucketResponse struct {
+	Name        string `json:"name"`
+	Loans       int    `json:"loans"`
+	Outstanding string `json:"outstanding"`
+}
+
+// PortfolioResponse reports the book as of AsOf, the latest snapshot on or
+// before Date.
+type PortfolioResponse struct {
+	Date        string                    `json:"date"`
+	AsOf        string                    `json:"asOf"`
+	Loans       int                       `json:"loans"`
+	Outstanding string                    `json:"outstanding"`
+	ByStatus    []PortfolioBucketResponse `json:"byStatus"`
+	ByDPD       []PortfolioBucketResponse `json:"byDpd"`
+}
+
+func NewLoanHistoryResponse(loanID int64, from, to time.Time, snapshots []loan.Snapshot) LoanHistoryResponse {
+	resp := LoanHistoryResponse{
+		LoanID:    strconv.FormatInt(loanID, 10),
+		From:      from.Format(time.DateOnly),
+		To:        to.Format(time.DateOnly),
+		Snapshots: make([]LoanSnapshotResponse, 0, len(snapshots)),
+	}
+	for _, s := range snapshots {
+		resp.Snapshots = append(resp.Snapshots, LoanSnapshotResponse{
+			Date:        s.Date.Format(time.DateOnly),
+			Status:      string(s.Status),
+			Outstanding: formatMoney(s.Outstanding),
+			DPD:         s.DPD,
+		})
+	}
+	return resp
+}
+
+func NewPortfolioResponse(report *loan.PortfolioReport) PortfolioResponse {
+	if report == nil {
+		return PortfolioResponse{ByStatus: []PortfolioBucketResponse{}, ByDPD: []PortfolioBucketResponse{}}
+	}
+	return PortfolioResponse{
+		Date:        report.RequestedDate.Format(time.DateOnly),
+		AsOf:        report.AsOf.Format(time.DateOnly),
+		Loans:       report.Loans,
+		Outstanding: formatMoney(report.Outstanding),
+		ByStatus:    newPortfolioBuckets(report.ByStatus),
+		ByDPD:       newPortfolioBuckets(report.ByDPD),
+	}
+}
+
+func newPortfolioBuckets(buckets []loan.PortfolioBucket) []PortfolioBucketResponse {
+	resp := make([]PortfolioBucketResponse, 0, len(buckets))
+	for _, b := range buckets {
+		resp = append(resp, PortfolioBucketResponse{Name: b.Name, Loans: b.Loans, Outstanding: formatMoney(b.Outstanding)})
+	}
+	return resp
+}
+
+func formatMoney(m loan.Money) string {
+	return decimal.NewFromFloat(m).StringFixed(2)
+}
+
+type ChannelCollectionsResponse struct {
+	Channel  string `json:"channel"`
+	Payments int    `json:"payments"`
+	Amount   string `json:"amount"`
+}
+
+// CollectionsByChannelResponse totals the payments received from From to To,
+// inclusive.
+type CollectionsByChannelResponse struct {
+	From      string                       `json:"from"`
+	To        string                       `json:"to"`
+	Payments  int                          `json:"payments"`
+	Amount    string                       `json:"amount"`
+	ByChannel []ChannelCollectionsResponse `json:"byChannel"`
+}
+
+func NewCollectionsByChannelResponse(report *loan.CollectionsReport) CollectionsByChannelResponse {
+	resp := CollectionsByChannelResponse{
+		From:      report.From.Format(time.DateOnly),
+		To:        report.To.Format(time.DateOnly),
+		Payments:  report.TotalPayments,
+		Amount:    formatMoney(report.TotalAmount),
+		ByChannel: make([]ChannelCollectionsResponse, 0, len(report.Channels)),
+	}
+	for _, c := range report.Channels {
+		resp.ByChannel = append(resp.ByChannel, ChannelCollectionsResponse{
+			Channel:  string(c.Channel),
+			Payments: c.Payments,
+			Amount:   formatMoney(c.Amount),
+		})
+	}
+	return resp
+}
+
+type TaxTotalsResponse struct {
+	Jurisdiction  string `json:"jurisdiction"`
+	FeeType       string `json:"feeType"`
+	Lines         int    `json:"lines"`
+	TaxableAmount string `json:"taxableAmount"`
+	Amount        string `json:"amount"`
+	WaivedAmount  string `json:"waivedAmount"`
+}
+
+// TaxReportResponse totals the tax charged on the fees posted from From to
+// To, inclusive. payable is amount less what was waived with its fees.
+type TaxReportResponse struct {
+	From    string              `json:"from"`
+	To      string              `json:"to"`
+	Amount  string              `json:"amount"`
+	Waived  string              `json:"waived"`
+	Payable string              `json:"payable"`
+	Rows    []TaxTotalsResponse `json:"rows"`
+}
+
+func NewTaxReportResponse(report *loan.TaxReport) TaxReportResponse {
+	resp := TaxReportResponse{
+		From:    report.From.Format(time.DateOnly),
+		To:      report.To.Format(time.DateOnly),
+		Amount:  formatMoney(report.TotalAmount),
+		Waived:  formatMoney(report.TotalWaived),
+		Payable: formatMoney(report.TotalPayable),
+		Rows:    make([]TaxTotalsResponse, 0, len(report.Rows)),
+	}
+	for _, row := range report.Rows {
+		resp.Rows = append(resp.Rows, TaxTotalsResponse{
+			Jurisdiction:  row.Jurisdiction,
+			FeeType:       string(row.FeeType),
+			Lines:         row.Lines,
+			TaxableAmount: formatMoney(row.TaxableAmount),
+			Amount:        formatMoney(row.Amount),
+			WaivedAmount:  formatMoney(row.WaivedAmount),
+		})
+	}
+	return resp
+}
@@ -0,0 +1,34 @@
+package dto
+
+import (
+	"billing-engine/internal/sandbox"
+	"fmt"
+	"time"
+)
+
+type AdvanceClockRequest struct {
+	Days int `json:"days"`
+}
+
+func (r *AdvanceClockRequest) Validate() error {
+	if r.Days < 1 || r.Days > sandbox.MaxAdvanceDays {
+		return fmt.Errorf("days must be between 1 and %d", sandbox.MaxAdvanceDays)
+	}
+	return nil
+}
+
+// SandboxClockResponse is the billing clock of a sandbox deployment;
+// OffsetDays is how far it runs ahead of the wall clock.
+type SandboxClockResponse struct {
+	Now        time.Time `json:"now"`
+	Today      string    `json:"today"`
+	OffsetDays int       `json:"offsetDays"`
+}
+
+func NewSandboxClockResponse(s sandbox.State) SandboxClockResponse {
+	return SandboxClockResponse{
+		Now:        s.Now,
+		Today:      s.Now.UTC().Format(time.DateOnly),
+		OffsetDays: s.OffsetDays,
+	}
+}
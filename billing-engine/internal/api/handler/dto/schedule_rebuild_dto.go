@@ -0,0 +1,52 @@
+package dto
+
+import (
+	"billing-engine/internal/domain/loan"
+	"strconv"
+)
+
+// ScheduleRebuildResponse lists the schedule changes of a rebuild, or of the
+// rebuild a dry run would make. Status is the loan status afterwards.
+type ScheduleRebuildResponse struct {
+	LoanID   string                   `json:"loanId"`
+	DryRun   bool                     `json:"dryRun"`
+	Status   string                   `json:"status"`
+	PaidKept int                      `json:"paidKept"`
+	Changes  []ScheduleChangeResponse `json:"changes"`
+}
+
+// ScheduleChangeResponse is one week of the diff: kind is ADDED, UPDATED or
+// REMOVED, before is absent for an added week and after for a removed one.
+type ScheduleChangeResponse struct {
+	Kind       string                 `json:"kind"`
+	WeekNumber int                    `json:"weekNumber"`
+	Before     *ScheduleEntryResponse `json:"before,omitempty"`
+	After      *ScheduleEntryResponse `json:"after,omitempty"`
+}
+
+func NewScheduleRebuildResponse(r *loan.ScheduleRebuild) ScheduleRebuildResponse {
+	resp := ScheduleRebuildResponse{
+		LoanID:   strconv.FormatInt(r.LoanID, 10),
+		DryRun:   r.DryRun,
+		Status:   string(r.Status),
+		PaidKept: r.PaidKept,
+		Changes:  make([]ScheduleChangeResponse, len(r.Changes)),
+	}
+	for i, change := range r.Changes {
+		resp.Changes[i] = newScheduleChangeResponse(change)
+	}
+	return resp
+}
+
+func newScheduleChangeResponse(change loan.ScheduleChange) ScheduleChangeResponse {
+	c := ScheduleChangeResponse{Kind: string(change.Kind), WeekNumber: change.WeekNumber}
+	if change.Before != nil {
+		before := NewScheduleEntryResponse(change.Before)
+		c.Before = &before
+	}
+	if change.After != nil {
+		after := NewScheduleEntryResponse(change.After)
+		c.After = &after
+	}
+	return c
+}
@@ -0,0 +1,77 @@
+package dto
+
+import (
+	"billing-engine/internal/infrastructure/monitoring"
+	"time"
+)
+
+// SLOStatsResponse describes the requests in the window. Latencies are in
+// milliseconds. A budgetBurnRate above 1 spends the error budget faster than
+// the window allows; budgetRemaining goes negative once it is overspent.
+type SLOStatsResponse struct {
+	Requests        uint64  `json:"requests"`
+	Errors          uint64  `json:"errors"`
+	ErrorRate       float64 `json:"errorRate"`
+	P95Ms           float64 `json:"p95Ms"`
+	P99Ms           float64 `json:"p99Ms"`
+	BudgetBurnRate  float64 `json:"budgetBurnRate"`
+	BudgetRemaining float64 `json:"budgetRemaining"`
+}
+
+type RouteSLOResponse struct {
+	Method          string  `json:"method"`
+	Route           string  `json:"route"`
+	Requests        uint64  `json:"requests"`
+	Errors          uint64  `json:"errors"`
+	ErrorRate       float64 `json:"errorRate"`
+	P95Ms           float64 `json:"p95Ms"`
+	P99Ms           float64 `json:"p99Ms"`
+	BudgetBurnRate  float64 `json:"budgetBurnRate"`
+	BudgetRemaining float64 `json:"budgetRemaining"`
+}
+
+// SLOSummaryResponse is the API health over the rolling window ending at At.
+type SLOSummaryResponse struct {
+	At        time.Time          `json:"at"`
+	Window    string             `json:"window" example:"1h0m0s"`
+	Objective float64            `json:"objective" example:"0.995"`
+	Overall   SLOStatsResponse   `json:"overall"`
+	Routes    []RouteSLOResponse `json:"routes"`
+}
+
+func NewSLOSummaryResponse(s monitoring.SLOSummary) SLOSummaryResponse {
+	routes := make([]RouteSLOResponse, len(s.Routes))
+	for i, r := range s.Routes {
+		stats := newSLOStatsResponse(r.SLOStats)
+		routes[i] = RouteSLOResponse{
+			Method:          r.Method,
+			Route:           r.Route,
+			Requests:        stats.Requests,
+			Errors:          stats.Errors,
+			ErrorRate:       stats.ErrorRate,
+			P95Ms:           stats.P95Ms,
+			P99Ms:           stats.P99Ms,
+			BudgetBurnRate:  stats.BudgetBurnRate,
+			BudgetRemaining: stats.BudgetRemaining,
+		}
+	}
+	return SLOSummaryResponse{
+		At:        s.At,
+		Window:    s.Window.String(),
+		Objective: s.Objective,
+		Overall:   newSLOStatsResponse(s.Overall),
+		Routes:    routes,
+	}
+}
+
+func newSLOStatsResponse(s monitoring.SLOStats) SLOStatsResponse {
+	return SLOStatsResponse{
+		Requests:        s.Requests,
+		Errors:          s.Errors,
+		ErrorRate:       s.ErrorRate,
+		P95Ms:           float64(s.P95) / float64(time.Millisecond),
+		P99Ms:           float64(s.P99) / float64(time.Millisecond),
+		BudgetBurnRate:  s.BudgetBurnRate,
+		BudgetRemaining: s.BudgetRemaining,
+	}
+}
@@ -0,0 +1,79 @@
+package dto
+
+import (
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/domain/summary"
+	"strconv"
+	"time"
+)
+
+// CustomerSummaryLoanResponse is the customer's current loan in a summary.
+type CustomerSummaryLoanResponse struct {
+	LoanID            string  `json:"loanId"`
+	PublicID          string  `json:"publicId,omitempty"`
+	Status            string  `json:"status"`
+	PrincipalAmount   string  `json:"principalAmount"`
+	TotalLoanAmount   string  `json:"totalLoanAmount"`
+	TotalPaid         string  `json:"totalPaid"`
+	Outstanding       string  `json:"outstanding"`
+	InstallmentsPaid  int     `json:"installmentsPaid"`
+	InstallmentsTotal int     `json:"installmentsTotal"`
+	NextDueDate       *string `json:"nextDueDate,omitempty"`
+	NextDueAmount     string  `json:"nextDueAmount"`
+	DaysPastDue       int     `json:"daysPastDue"`
+	Bucket            string  `json:"bucket"`
+}
+
+type CustomerSummaryResponse struct {
+	CustomerID   string                       `json:"customerId"`
+	PublicID     string                       `json:"publicId,omitempty"`
+	Name         string                       `json:"name"`
+	Address      string                       `json:"address"`
+	Active       bool                         `json:"active"`
+	IsDelinquent bool                         `json:"isDelinquent"`
+	Loan         *CustomerSummaryLoanResponse `json:"loan,omitempty"`
+	RefreshedAt  time.Time                    `json:"refreshedAt"`
+}
+
+func NewCustomerSummaryResponse(s *summary.CustomerLoanSummary) CustomerSummaryResponse {
+	if s == nil {
+		return CustomerSummaryResponse{}
+	}
+	resp := CustomerSummaryResponse{
+		CustomerID:   strconv.FormatInt(s.CustomerID, 10),
+		PublicID:     publicIDString(s.CustomerPublicID),
+		Name:         s.Name,
+		Address:      s.Address,
+		Active:       s.Active,
+		IsDelinquent: s.IsDelinquent,
+		RefreshedAt:  s.RefreshedAt,
+	}
+	if s.LoanID == nil {
+		return resp
+	}
+
+	l := &CustomerSummaryLoanResponse{
+		LoanID:            strconv.FormatInt(*s.LoanID, 10),
+		PrincipalAmount:   formatMoney(s.PrincipalAmount),
+		TotalLoanAmount:   formatMoney(s.TotalLoanAmount),
+		TotalPaid:         formatMoney(s.TotalPaid),
+		Outstanding:       formatMoney(s.Outstanding),
+		InstallmentsPaid:  s.InstallmentsPaid,
+		InstallmentsTotal: s.InstallmentsTotal,
+		NextDueAmount:     formatMoney(s.NextDueAmount),
+		DaysPastDue:       s.DaysPastDue,
+		Bucket:            loan.DPDBucket(s.DaysPastDue),
+	}
+	if s.LoanPublicID != nil {
+		l.PublicID = publicIDString(*s.LoanPublicID)
+	}
+	if s.LoanStatus != nil {
+		l.Status = string(*s.LoanStatus)
+	}
+	if s.NextDueDate != nil {
+		date := s.NextDueDate.Format(time.DateOnly)
+		l.NextDueDate = &date
+	}
+	resp.Loan = l
+	return resp
+}
@@ -0,0 +1,45 @@
+package dto
+
+import (
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/domain/summary"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCustomerSummaryResponse(t *testing.T) {
+	t.Run("includes the current loan", func(t *testing.T) {
+		loanID := int64(3)
+		loanPublicID := uuid.New()
+		status := loan.StatusDelinquent
+		due := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+
+		resp := NewCustomerSummaryResponse(&summary.CustomerLoanSummary{
+			CustomerID: 7, Name: "Jane Doe", IsDelinquent: true,
+			LoanID: &loanID, LoanPublicID: &loanPublicID, LoanStatus: &status,
+			PrincipalAmount: 5000000, TotalLoanAmount: 5500000, Outstanding: 5280000,
+			InstallmentsPaid: 2, InstallmentsTotal: 50, NextDueDate: &due, NextDueAmount: 110000, DaysPastDue: 21,
+		})
+
+		assert.Equal(t, "7", resp.CustomerID)
+		assert.Empty(t, resp.PublicID)
+		require.NotNil(t, resp.Loan)
+		assert.Equal(t, "3", resp.Loan.LoanID)
+		assert.Equal(t, loanPublicID.String(), resp.Loan.PublicID)
+		assert.Equal(t, "DELINQUENT", resp.Loan.Status)
+		assert.Equal(t, "110000.00", resp.Loan.NextDueAmount)
+		assert.Equal(t, "2025-03-10", *resp.Loan.NextDueDate)
+		assert.Equal(t, loan.DPDBucket(21), resp.Loan.Bucket)
+	})
+
+	t.Run("omits the loan of a customer without one", func(t *testing.T) {
+		resp := NewCustomerSummaryResponse(&summary.CustomerLoanSummary{CustomerID: 7, Name: "Jane Doe", Active: true})
+
+		assert.True(t, resp.Active)
+		assert.Nil(t, resp.Loan)
+	})
+}
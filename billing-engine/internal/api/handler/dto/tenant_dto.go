@@ -0,0 +1,76 @@
+package dto
+
+import (
+	"billing-engine/internal/domain/tenant"
+	"fmt"
+	"time"
+)
+
+// UpdateTenantSettingsRequest replaces every override. An omitted field goes
+// back to the configured value.
+type UpdateTenantSettingsRequest struct {
+	MissedPayments *int     `json:"missedPayments,omitempty"`
+	CurePayments   *int     `json:"curePayments,omitempty"`
+	RateLimitRPS   *float64 `json:"rateLimitRps,omitempty"`
+	RateLimitBurst *int     `json:"rateLimitBurst,omitempty"`
+	GraceDays      *int     `json:"graceDays,omitempty"`
+	// LateFee is a decimal amount, such as "15.00".
+	LateFee            *string `json:"lateFee,omitempty"`
+	ReminderWindowDays *int    `json:"reminderWindowDays,omitempty"`
+}
+
+func (r *UpdateTenantSettingsRequest) Validate() error {
+	if _, err := optionalAmount(r.LateFee); err != nil {
+		return fmt.Errorf("invalid lateFee: %w", err)
+	}
+	return nil
+}
+
+// Overrides converts the request. Call it after Validate.
+func (r *UpdateTenantSettingsRequest) Overrides() tenant.Overrides {
+	lateFee, _ := optionalAmount(r.LateFee)
+	return tenant.Overrides{
+		MissedPayments:     r.MissedPayments,
+		CurePayments:       r.CurePayments,
+		RateLimitRPS:       r.RateLimitRPS,
+		RateLimitBurst:     r.RateLimitBurst,
+		GraceDays:          r.GraceDays,
+		LateFee:            lateFee,
+		ReminderWindowDays: r.ReminderWindowDays,
+	}
+}
+
+// TenantSettingsResponse lists the tenant's overrides; a missing one means
+// the configured value applies. It has no updatedAt until settings were
+// first stored.
+type TenantSettingsResponse struct {
+	TenantID           string     `json:"tenantId"`
+	MissedPayments     *int       `json:"missedPayments,omitempty"`
+	CurePayments       *int       `json:"curePayments,omitempty"`
+	RateLimitRPS       *float64   `json:"rateLimitRps,omitempty"`
+	RateLimitBurst     *int       `json:"rateLimitBurst,omitempty"`
+	GraceDays          *int       `json:"graceDays,omitempty"`
+	LateFee            *string    `json:"lateFee,omitempty"`
+	ReminderWindowDays *int       `json:"reminderWindowDays,omitempty"`
+	UpdatedBy          string     `json:"updatedBy,omitempty"`
+	UpdatedAt          *time.Time `json:"updatedAt,omitempty"`
+}
+
+func NewTenantSettingsResponse(s *tenant.Settings) TenantSettingsResponse {
+	resp := TenantSettingsResponse{
+		TenantID:           s.TenantID,
+		MissedPayments:     s.Overrides.MissedPayments,
+		CurePayments:       s.Overrides.CurePayments,
+		RateLimitRPS:       s.Overrides.RateLimitRPS,
+		RateLimitBurst:     s.Overrides.RateLimitBurst,
+		GraceDays:          s.Overrides.GraceDays,
+		LateFee:            optionalMoney(s.Overrides.LateFee),
+		ReminderWindowDays: s.Overrides.ReminderWindowDays,
+		UpdatedBy:          s.UpdatedBy,
+	}
+	if !s.UpdatedAt.IsZero() {
+		updatedAt := s.UpdatedAt
+		resp.UpdatedAt = &updatedAt
+	}
+	return resp
+}
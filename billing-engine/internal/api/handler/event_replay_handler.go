@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"billing-engine/internal/api/handler/dto"
+	"billing-engine/internal/event"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const jobKindEventReplay = "event-replay"
+
+// EventReplayHandler lets admins inspect the event log and publish events
+// again for consumers that missed them.
+type EventReplayHandler struct {
+	service event.ReplayService
+	bulk    *BulkRunner
+	logger  *slog.Logger
+}
+
+// NewEventReplayHandler replays in the background, as a job on bulk, when
+// the client sends Prefer: respond-async. A nil bulk always replays within
+// the request.
+func NewEventReplayHandler(s event.ReplayService, bulk *BulkRunner, l *slog.Logger) *EventReplayHandler {
+	if s == nil {
+		panic("replay service cannot be nil")
+	}
+	if l == nil {
+		panic("logger cannot be nil")
+	}
+	h := &EventReplayHandler{service: s, bulk: bulk, logger: l.With("component", "EventReplayHandler")}
+	registerBulkTask(bulk, jobKindEventReplay, h.replay)
+	return h
+}
+
+// replayFilterFromQuery reads the same criteria as ReplayEventsRequest from
+// the query string.
+func replayFilterFromQuery(r *http.Request) (event.ReplayFilter, error) {
+	q := r.URL.Query()
+	var req dto.ReplayEventsRequest
+	if raw := q.Get("entity_id"); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return event.ReplayFilter{}, fmt.Errorf("%w: entity_id must be an integer", apperrors.ErrInvalidArgument)
+		}
+		req.EntityID = &id
+	}
+	if raw := q.Get("types"); raw != "" {
+		req.Types = strings.Split(raw, ",")
+	}
+	for name, dst := range map[string]**time.Time{"since": &req.Since, "until": &req.Until} {
+		if raw := q.Get(name); raw != "" {
+			t, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				return event.ReplayFilter{}, fmt.Errorf("%w: %s must be an RFC 3339 timestamp", apperrors.ErrInvalidArgument, name)
+			}
+			*dst = &t
+		}
+	}
+	if raw := q.Get("unpublished"); raw != "" {
+		unpublished, err := strconv.ParseBool(raw)
+		if err != nil {
+			return event.ReplayFilter{}, fmt.Errorf("%w: unpublished must be true or false", apperrors.ErrInvalidArgument)
+		}
+		req.UnpublishedOnly = unpublished
+	}
+	if raw := q.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil {
+			return event.ReplayFilter{}, fmt.Errorf("%w: limit must be an integer", apperrors.ErrInvalidArgument)
+		}
+		req.Limit = limit
+	}
+	if err := req.Validate(); err != nil {
+		return event.ReplayFilter{}, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err)
+	}
+	return req.Filter(), nil
+}
+
+// ListEvents handles GET /admin/events
+// @Summary List recorded events
+// @Description Lists the events in the event log that a replay with the same criteria would publish, oldest first. At least one criterion is required.
+// @Tags Events
+// @Produce json
+// @Param entity_id query int false "Customer the events are about"
+// @Param types query string false "Comma separated event types, e.g. customer.created,customer.updated"
+// @Param since query string false "Earliest occurrence, RFC 3339, inclusive"
+// @Param until query string false "Latest occurrence, RFC 3339, exclusive"
+// @Param unpublished query bool false "Only events the broker never accepted"
+// @Param limit query int false "Maximum number of events, default 1000"
+// @Success 200 {array} dto.EventRecordResponse
+// @Failure 400 {object} dto.ErrorResponse "Missing or invalid criteria"
+// @Failure 403 {object} dto.ErrorResponse "Token lacks the admin scope"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /admin/events [get]
+// @Security BearerAuth
+func (h *EventReplayHandler) ListEvents(w http.ResponseWriter, r *http.Request) {
+	filter, err := replayFilterFromQuery(r)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	records, err := h.service.Find(r.Context(), filter)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, dto.NewEventRecordListResponse(records))
+}
+
+// ReplayEvents handles POST /admin/events/replay
+// @Summary Replay recorded events
+// @Description Publishes the matching events to the broker again under their original IDs, oldest first, so that consumers that were down can be backfilled. Consumers that already processed an event skip it. With `Prefer: respond-async` the replay runs in the background: the answer is 202 with the job to poll at the Location header, whose result is the same report.
+// @Tags Events
+// @Accept json
+// @Produce json
+// @Param request body dto.ReplayEventsRequest true "Events to replay"
+// @Success 200 {object} dto.ReplayReportResponse
+// @Success 202 {object} dto.JobResponse "Replay continues in the background"
+// @Failure 400 {object} dto.ErrorResponse "Missing or invalid criteria"
+// @Failure 403 {object} dto.ErrorResponse "Token lacks the admin scope"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Failure 503 {object} dto.ErrorResponse "RabbitMQ is not connected, or too many jobs waiting"
+// @Router /admin/events/replay [post]
+// @Security BearerAuth
+func (h *EventReplayHandler) ReplayEvents(w http.ResponseWriter, r *http.Request) {
+	var req dto.ReplayEventsRequest
+	if err := decodeJSON(r, &req); err != nil {
+		respondError(w, fmt.Errorf("%w: %w", apperrors.ErrInvalidArgument, err))
+		return
+	}
+	if err := req.Validate(); err != nil {
+		respondError(w, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err))
+		return
+	}
+
+	h.logger.WarnContext(r.Context(), "Event replay requested", slog.String("by", actorFromContext(r.Context())))
+	respondBulk(h.bulk, w, r, jobKindEventReplay, 0, req.Filter(), h.replay)
+}
+
+func (h *EventReplayHandler) replay(ctx context.Context, filter event.ReplayFilter, _ func(int)) (any, error) {
+	report, err := h.service.Replay(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return dto.NewReplayReportResponse(report), nil
+}
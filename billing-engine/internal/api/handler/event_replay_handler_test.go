@@ -0,0 +1,135 @@
+package handler_test
+
+import (
+	"billing-engine/internal/api/handler"
+	"billing-engine/internal/api/handler/dto"
+	"billing-engine/internal/event"
+	"billing-engine/internal/jobs"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockReplayService struct {
+	mock.Mock
+}
+
+func (m *MockReplayService) Find(ctx context.Context, f event.ReplayFilter) ([]event.Record, error) {
+	args := m.Called(ctx, f)
+	records, _ := args.Get(0).([]event.Record)
+	return records, args.Error(1)
+}
+
+func (m *MockReplayService) Replay(ctx context.Context, f event.ReplayFilter) (*event.ReplayReport, error) {
+	args := m.Called(ctx, f)
+	report, _ := args.Get(0).(*event.ReplayReport)
+	return report, args.Error(1)
+}
+
+func TestEventReplayHandlerListEvents(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	t.Run("parses the criteria", func(t *testing.T) {
+		svc := new(MockReplayService)
+		h := handler.NewEventReplayHandler(svc, nil, logger)
+		entity := int64(7)
+		since := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+		svc.On("Find", mock.Anything, event.ReplayFilter{
+			EntityID: &entity, Types: []string{"customer.created", "customer.updated"}, Since: &since, UnpublishedOnly: true, Limit: 50,
+		}).Return([]event.Record{{ID: 1, EventID: "e-1", Type: event.TypeCustomerCreated, EntityID: 7, Payload: json.RawMessage(`{}`)}}, nil)
+
+		rec := httptest.NewRecorder()
+		h.ListEvents(rec, httptest.NewRequest(http.MethodGet,
+			"/admin/events?entity_id=7&types=customer.created,customer.updated&since=2025-03-01T00:00:00Z&unpublished=true&limit=50", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var resp []dto.EventRecordResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		require.Len(t, resp, 1)
+		assert.Equal(t, "e-1", resp[0].EventID)
+		svc.AssertExpectations(t)
+	})
+
+	for name, query := range map[string]string{
+		"entity":      "entity_id=x",
+		"since":       "since=2025-03-01",
+		"unpublished": "unpublished=maybe",
+		"limit":       "limit=-1",
+	} {
+		t.Run("invalid "+name, func(t *testing.T) {
+			svc := new(MockReplayService)
+			rec := httptest.NewRecorder()
+			handler.NewEventReplayHandler(svc, nil, logger).ListEvents(rec, httptest.NewRequest(http.MethodGet, "/admin/events?"+query, nil))
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			svc.AssertNotCalled(t, "Find", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestEventReplayHandlerReplayEvents(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	t.Run("reports the replay", func(t *testing.T) {
+		svc := new(MockReplayService)
+		svc.On("Replay", mock.Anything, event.ReplayFilter{Types: []string{"customer.updated"}}).
+			Return(&event.ReplayReport{Matched: 3, Replayed: 2, Failed: []string{"e-3"}}, nil)
+
+		rec := httptest.NewRecorder()
+		handler.NewEventReplayHandler(svc, nil, logger).ReplayEvents(rec, httptest.NewRequest(http.MethodPost, "/admin/events/replay",
+			strings.NewReader(`{"types":["customer.updated"]}`)))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"matched":3,"replayed":2,"failed":["e-3"]}`, rec.Body.String())
+	})
+
+	t.Run("broker not connected", func(t *testing.T) {
+		svc := new(MockReplayService)
+		svc.On("Replay", mock.Anything, mock.Anything).Return(nil, apperrors.ErrUnavailable)
+
+		rec := httptest.NewRecorder()
+		handler.NewEventReplayHandler(svc, nil, logger).ReplayEvents(rec, httptest.NewRequest(http.MethodPost, "/admin/events/replay",
+			strings.NewReader(`{"unpublishedOnly":true}`)))
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	})
+
+	t.Run("replays in the background when the client prefers so", func(t *testing.T) {
+		svc := new(MockReplayService)
+		svc.On("Replay", mock.Anything, event.ReplayFilter{UnpublishedOnly: true}).
+			Return(&event.ReplayReport{Matched: 1, Replayed: 1}, nil).Once()
+		runner := jobs.NewRunner(jobs.NewMemoryStore(), jobs.Config{Workers: 1, PollInterval: 5 * time.Millisecond}, nil, logger)
+		h := handler.NewEventReplayHandler(svc, handler.NewBulkRunner(runner, 0, logger), logger)
+		runner.Start(context.Background())
+		t.Cleanup(func() { _ = runner.Stop(context.Background()) })
+
+		req := httptest.NewRequest(http.MethodPost, "/admin/events/replay", strings.NewReader(`{"unpublishedOnly":true}`))
+		req.Header.Set("Prefer", "respond-async")
+		rec := httptest.NewRecorder()
+		h.ReplayEvents(rec, req)
+
+		require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+		var job dto.JobResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&job))
+		assert.Equal(t, "event-replay", job.Kind)
+		require.Eventually(t, func() bool {
+			polled, err := runner.Get(context.Background(), job.ID)
+			return err == nil && polled.Status == jobs.StatusSucceeded
+		}, time.Second, 5*time.Millisecond)
+		polled, err := runner.Get(context.Background(), job.ID)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"matched":1,"replayed":1,"failed":[]}`, string(polled.Result))
+		svc.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"billing-engine/internal/event"
+	"billing-engine/internal/pkg/apperrors"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const eventStreamRetryMillis = 3000
+
+type EventStreamHandler struct {
+	hub       *event.Hub
+	heartbeat time.Duration
+	logger    *slog.Logger
+}
+
+func NewEventStreamHandler(hub *event.Hub, heartbeat time.Duration, l *slog.Logger) *EventStreamHandler {
+	if heartbeat <= 0 {
+		heartbeat = 15 * time.Second
+	}
+	return &EventStreamHandler{
+		hub:       hub,
+		heartbeat: heartbeat,
+		logger:    l.With("component", "EventStreamHandler"),
+	}
+}
+
+// Stream relays loan and customer domain events as server-sent events.
+//
+// @Summary Stream billing events
+// @Description Opens a server-sent events stream of loan and customer domain events. Filter with a comma separated `types` list and resume after a reconnect with the Last-Event-ID header. Browsers that cannot set headers may pass the bearer token as `access_token`.
+// @Tags Events
+// @Produce text/event-stream
+// @Param types query string false "Comma separated event types, e.g. loan.created,customer.updated"
+// @Param access_token query string false "Bearer token for clients that cannot set the Authorization header"
+// @Success 200 {string} string "Event stream"
+// @Failure 400 {object} dto.ErrorResponse "Unknown event type"
+// @Failure 401 {object} dto.ErrorResponse "Missing or invalid bearer token"
+// @Failure 403 {object} dto.ErrorResponse "Customer scoped token"
+// @Router /events/stream [get]
+// @Security BearerAuth
+func (h *EventStreamHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	var types []string
+	if raw := r.URL.Query().Get("types"); raw != "" {
+		for _, t := range strings.Split(raw, ",") {
+			t = strings.TrimSpace(t)
+			if !event.IsStreamEventType(t) {
+				respondError(w, fmt.Errorf("%w: unknown event type %q", apperrors.ErrInvalidArgument, t))
+				return
+			}
+			types = append(types, t)
+		}
+	}
+
+	var lastEventID int64
+	if raw := r.Header.Get("Last-Event-ID"); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || id < 0 {
+			respondError(w, fmt.Errorf("%w: invalid Last-Event-ID %q", apperrors.ErrInvalidArgument, raw))
+			return
+		}
+		lastEventID = id
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		respondError(w, fmt.Errorf("%w: streaming is not supported by the response writer", apperrors.ErrInternalServer))
+		return
+	}
+	// The server write timeout would otherwise cut the stream short.
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	sub := h.hub.Subscribe(types, lastEventID)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", eventStreamRetryMillis)
+	flusher.Flush()
+
+	h.logger.InfoContext(r.Context(), "Event stream opened", slog.Any("types", types), slog.Int64("lastEventID", lastEventID))
+	defer h.logger.InfoContext(r.Context(), "Event stream closed")
+
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case env, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(env)
+			if err != nil {
+				h.logger.ErrorContext(r.Context(), "Failed to marshal stream event", slog.Any("error", err))
+				continue
+			}
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", env.ID, env.Type, data)
+			flusher.Flush()
+		case <-ticker.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"billing-engine/internal/event"
+	"bufio"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventStreamHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	t.Run("Streams filtered events", func(t *testing.T) {
+		hub := event.NewHub(8, 8, logger)
+		hub.Broadcast(event.TypeLoanCreated, map[string]int{"loanId": 1})
+		srv := httptest.NewServer(http.HandlerFunc(NewEventStreamHandler(hub, time.Hour, logger).Stream))
+		defer srv.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"?types=loan.created", nil)
+		req.Header.Set("Last-Event-ID", "0")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+		reader := bufio.NewReader(resp.Body)
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		assert.Equal(t, "retry: 3000\n", line)
+		_, _ = reader.ReadString('\n')
+
+		hub.Broadcast(event.TypeCustomerCreated, map[string]int{"customerId": 2})
+		hub.Broadcast(event.TypeLoanCreated, map[string]int{"loanId": 3})
+
+		var frame []string
+		for len(frame) < 3 {
+			line, err := reader.ReadString('\n')
+			require.NoError(t, err)
+			frame = append(frame, strings.TrimSuffix(line, "\n"))
+		}
+		assert.Equal(t, "id: 3", frame[0])
+		assert.Equal(t, "event: loan.created", frame[1])
+		assert.Contains(t, frame[2], `"payload":{"loanId":3}`)
+	})
+
+	t.Run("Rejects unknown event type", func(t *testing.T) {
+		h := NewEventStreamHandler(event.NewHub(1, 0, logger), time.Second, logger)
+		rr := httptest.NewRecorder()
+
+		h.Stream(rr, httptest.NewRequest(http.MethodGet, "/events/stream?types=loan.deleted", nil))
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("Rejects invalid Last-Event-ID", func(t *testing.T) {
+		h := NewEventStreamHandler(event.NewHub(1, 0, logger), time.Second, logger)
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/events/stream", nil)
+		req.Header.Set("Last-Event-ID", "abc")
+
+		h.Stream(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"billing-engine/internal/api/graphql"
+	"billing-engine/internal/api/handler/dto"
+	"billing-engine/internal/pkg/apperrors"
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+type GraphQLHandler struct {
+	schema *graphql.Schema
+	logger *slog.Logger
+}
+
+func NewGraphQLHandler(schema *graphql.Schema, l *slog.Logger) *GraphQLHandler {
+	return &GraphQLHandler{
+		schema: schema,
+		logger: l.With("component", "GraphQLHandler"),
+	}
+}
+
+// Query executes a read-only GraphQL query.
+//
+// @Summary Execute a GraphQL query
+// @Description Runs a read-only GraphQL query over customers, loans, schedules and payments so nested data can be fetched in one request. Query errors are returned in the errors array with HTTP 200.
+// @Tags GraphQL
+// @Accept json
+// @Produce json
+// @Param request body dto.GraphQLRequest true "GraphQL query"
+// @Success 200 {object} dto.GraphQLResponse "Query result"
+// @Failure 400 {object} dto.ErrorResponse "Invalid request payload"
+// @Failure 401 {object} dto.ErrorResponse "Missing or invalid bearer token"
+// @Failure 403 {object} dto.ErrorResponse "Customer scoped token"
+// @Router /graphql [post]
+// @Security BearerAuth
+func (h *GraphQLHandler) Query(w http.ResponseWriter, r *http.Request) {
+	var req dto.GraphQLRequest
+	if err := decodeJSON(r, &req); err != nil {
+		respondError(w, fmt.Errorf("%w: %w", apperrors.ErrInvalidArgument, err))
+		return
+	}
+	if err := req.Validate(); err != nil {
+		respondError(w, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err))
+		return
+	}
+
+	result := h.schema.Execute(r.Context(), req.Query, req.Variables, req.OperationName)
+
+	var resp dto.GraphQLResponse
+	if result.Data != nil {
+		resp.Data = result.Data
+	}
+	for _, e := range result.Errors {
+		resp.Errors = append(resp.Errors, dto.GraphQLError{Message: e.Message, Path: e.Path})
+	}
+	if len(resp.Errors) > 0 {
+		h.logger.WarnContext(r.Context(), "GraphQL query returned errors", slog.Int("count", len(resp.Errors)), slog.String("operationName", req.OperationName))
+	}
+	respondJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"billing-engine/internal/api/graphql"
+	"billing-engine/internal/domain/loan"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGraphQLHandlerQuery(t *testing.T) {
+	loanService := new(MockLoanService)
+	loanService.On("GetLoan", mock.Anything, int64(7), true).Return(&loan.Loan{
+		ID: 7, PrincipalAmount: 5000, Status: loan.StatusActive, StartDate: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}, nil)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	h := NewGraphQLHandler(graphql.NewBillingSchema(loanService, &stubCustomerService{}), logger)
+
+	t.Run("Success", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"{ loan(id: 7) { id status principalAmount } }"}`))
+		rr := httptest.NewRecorder()
+
+		h.Query(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"data":{"loan":{"id":"7","status":"ACTIVE","principalAmount":"5000.00"}}}`, rr.Body.String())
+	})
+
+	t.Run("Validation error", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"{ loan(id: 7) { secret } }"}`))
+		rr := httptest.NewRecorder()
+
+		h.Query(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"data":null,"errors":[{"message":"cannot query field \"secret\" on type \"Loan\"","path":["loan","secret"]}]}`, rr.Body.String())
+	})
+
+	t.Run("Empty query", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"  "}`))
+		rr := httptest.NewRecorder()
+
+		h.Query(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"billing-engine/internal/api/handler/dto"
+	"billing-engine/internal/domain/integrity"
+	"log/slog"
+	"net/http"
+)
+
+type IntegrityHandler struct {
+	service integrity.Service
+	logger  *slog.Logger
+}
+
+func NewIntegrityHandler(s integrity.Service, l *slog.Logger) *IntegrityHandler {
+	if s == nil {
+		panic("integrity service cannot be nil")
+	}
+	if l == nil {
+		panic("logger cannot be nil")
+	}
+	return &IntegrityHandler{service: s, logger: l.With("component", "IntegrityHandler")}
+}
+
+// ListFindings handles GET /admin/integrity/findings
+// @Summary List data integrity findings
+// @Description Lists the violations the nightly integrity job found on its last run, oldest first. The checks are customer_loan_missing, ledger_mismatch, paid_off_unpaid and orphan_schedule; a violation keeps its firstSeenAt for as long as it is found again.
+// @Tags Monitoring
+// @Produce json
+// @Param check query string false "Only findings of this check"
+// @Success 200 {array} dto.IntegrityFindingResponse "Findings"
+// @Failure 400 {object} dto.ErrorResponse "Unknown check"
+// @Failure 403 {object} dto.ErrorResponse "Token lacks the admin scope"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /admin/integrity/findings [get]
+// @Security BearerAuth
+func (h *IntegrityHandler) ListFindings(w http.ResponseWriter, r *http.Request) {
+	findings, err := h.service.ListFindings(r.Context(), r.URL.Query().Get("check"))
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "Service failed to list integrity findings", slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, dto.NewIntegrityFindingListResponse(findings))
+}
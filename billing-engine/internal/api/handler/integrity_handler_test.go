@@ -0,0 +1,82 @@
+package handler_test
+
+import (
+	"billing-engine/internal/api/handler"
+	"billing-engine/internal/api/handler/dto"
+	"billing-engine/internal/domain/integrity"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockIntegrityService struct {
+	mock.Mock
+}
+
+func (m *MockIntegrityService) Run(ctx context.Context) (*integrity.Report, error) {
+	args := m.Called(ctx)
+	r, _ := args.Get(0).(*integrity.Report)
+	return r, args.Error(1)
+}
+
+func (m *MockIntegrityService) ListFindings(ctx context.Context, check string) ([]integrity.Finding, error) {
+	args := m.Called(ctx, check)
+	f, _ := args.Get(0).([]integrity.Finding)
+	return f, args.Error(1)
+}
+
+func TestIntegrityHandlerListFindings(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	t.Run("lists the findings of a check", func(t *testing.T) {
+		svc := new(MockIntegrityService)
+		seen := time.Date(2025, 3, 10, 4, 30, 0, 0, time.UTC)
+		f := integrity.LedgerMismatch(7, 220, 110)
+		f.ID, f.FirstSeenAt, f.LastSeenAt = 3, seen, seen
+		svc.On("ListFindings", mock.Anything, "ledger_mismatch").Return([]integrity.Finding{f}, nil).Once()
+
+		rec := httptest.NewRecorder()
+		handler.NewIntegrityHandler(svc, logger).ListFindings(rec, httptest.NewRequest(http.MethodGet, "/admin/integrity/findings?check=ledger_mismatch", nil))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		var resp []dto.IntegrityFindingResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		require.Len(t, resp, 1)
+		assert.Equal(t, "3", resp[0].ID)
+		assert.Equal(t, "ledger_mismatch", resp[0].Check)
+		assert.Equal(t, "loan", resp[0].EntityType)
+		assert.Equal(t, "7", resp[0].EntityID)
+		assert.Equal(t, seen, resp[0].FirstSeenAt)
+	})
+
+	t.Run("answers an empty list when all is well", func(t *testing.T) {
+		svc := new(MockIntegrityService)
+		svc.On("ListFindings", mock.Anything, "").Return([]integrity.Finding{}, nil).Once()
+
+		rec := httptest.NewRecorder()
+		handler.NewIntegrityHandler(svc, logger).ListFindings(rec, httptest.NewRequest(http.MethodGet, "/admin/integrity/findings", nil))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `[]`, rec.Body.String())
+	})
+
+	t.Run("rejects an unknown check", func(t *testing.T) {
+		svc := new(MockIntegrityService)
+		svc.On("ListFindings", mock.Anything, "nope").Return(nil, apperrors.ErrInvalidArgument).Once()
+
+		rec := httptest.NewRecorder()
+		handler.NewIntegrityHandler(svc, logger).ListFindings(rec, httptest.NewRequest(http.MethodGet, "/admin/integrity/findings?check=nope", nil))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"billing-engine/internal/api/handler/dto"
+	"billing-engine/internal/jobs"
+	"billing-engine/internal/pkg/apperrors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	defaultListJobs = 50
+	maxListJobs     = 500
+)
+
+var errJobsDisabled = fmt.Errorf("%w: background jobs are not configured", apperrors.ErrUnavailable)
+
+// JobHandler reports on the async jobs: bulk uploads, event replays and
+// batch runs. runner is nil when no job runner is configured and lookups are
+// refused.
+type JobHandler struct {
+	runner *jobs.Runner
+	logger *slog.Logger
+}
+
+func NewJobHandler(runner *jobs.Runner, l *slog.Logger) *JobHandler {
+	if l == nil {
+		panic("logger cannot be nil")
+	}
+	return &JobHandler{runner: runner, logger: l.With("component", "JobHandler")}
+}
+
+// GetJob handles GET /jobs/{jobID}
+// @Summary Get the status of a job
+// @Description Returns the state of an async job, such as one started by a request answered with 202 Accepted: QUEUED, RUNNING, SUCCEEDED or FAILED, with the items processed so far. Once it succeeded, result holds the report the request would have returned synchronously. Any instance answers for any job; finished jobs are kept for an hour (jobs.retention).
+// @Tags Jobs
+// @Produce json
+// @Param jobID path string true "Job ID from the Location header"
+// @Success 200 {object} dto.JobResponse
+// @Failure 404 {object} dto.ErrorResponse "Unknown or expired job"
+// @Failure 503 {object} dto.ErrorResponse "Background jobs are not configured"
+// @Router /jobs/{jobID} [get]
+// @Security BearerAuth
+func (h *JobHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	if h.runner == nil {
+		respondError(w, errJobsDisabled)
+		return
+	}
+	job, err := h.runner.Get(r.Context(), chi.URLParam(r, "jobID"))
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, dto.NewJobResponse(job))
+}
+
+// ListJobs handles GET /jobs
+// @Summary List jobs
+// @Description Lists the async jobs still kept, newest first. Batch runs are jobs of kind batch.<name>, e.g. batch.DelinquencyUpdate.
+// @Tags Jobs
+// @Produce json
+// @Param kind query string false "Only jobs of this kind, e.g. customer-import"
+// @Param status query string false "Only jobs in this state: QUEUED, RUNNING, SUCCEEDED or FAILED"
+// @Param limit query int false "Maximum number of jobs, default 50, at most 500"
+// @Success 200 {array} dto.JobResponse
+// @Failure 400 {object} dto.ErrorResponse "Invalid status or limit"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Failure 503 {object} dto.ErrorResponse "Background jobs are not configured"
+// @Router /jobs [get]
+// @Security BearerAuth
+func (h *JobHandler) ListJobs(w http.ResponseWriter, r *http.Request) {
+	if h.runner == nil {
+		respondError(w, errJobsDisabled)
+		return
+	}
+	q := r.URL.Query()
+	filter := jobs.Filter{Kind: q.Get("kind"), Status: jobs.Status(q.Get("status")), Limit: defaultListJobs}
+	switch filter.Status {
+	case "", jobs.StatusQueued, jobs.StatusRunning, jobs.StatusSucceeded, jobs.StatusFailed:
+	default:
+		respondError(w, fmt.Errorf("%w: status must be QUEUED, RUNNING, SUCCEEDED or FAILED", apperrors.ErrInvalidArgument))
+		return
+	}
+	if raw := q.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxListJobs {
+			respondError(w, fmt.Errorf("%w: limit must be between 1 and %d", apperrors.ErrInvalidArgument, maxListJobs))
+			return
+		}
+		filter.Limit = n
+	}
+	found, err := h.runner.List(r.Context(), filter)
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "Failed to list jobs", slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, dto.NewJobListResponse(found))
+}
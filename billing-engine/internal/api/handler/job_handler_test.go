@@ -0,0 +1,81 @@
+package handler_test
+
+import (
+	"billing-engine/internal/api/handler"
+	"billing-engine/internal/api/handler/dto"
+	"billing-engine/internal/jobs"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobHandlerGetJob(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	get := func(h *handler.JobHandler, id string) *httptest.ResponseRecorder {
+		router := chi.NewRouter()
+		router.Get("/jobs/{jobID}", h.GetJob)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/jobs/"+id, nil))
+		return rec
+	}
+
+	t.Run("unknown job", func(t *testing.T) {
+		runner := jobs.NewRunner(jobs.NewMemoryStore(), jobs.Config{}, nil, logger)
+		rec := get(handler.NewJobHandler(runner, logger), "3f2b9c1e-0000-4000-8000-000000000000")
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("no job runner", func(t *testing.T) {
+		rec := get(handler.NewJobHandler(nil, logger), "anything")
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Contains(t, rec.Body.String(), "not configured")
+	})
+}
+
+func TestJobHandlerListJobs(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	// The runner is not started, so the jobs stay queued.
+	runner := jobs.NewRunner(jobs.NewMemoryStore(), jobs.Config{}, nil, logger)
+	noop := func(context.Context, jobs.Job, func(int)) (any, error) { return nil, nil }
+	runner.Register("customer-import", noop)
+	runner.Register("batch.DelinquencyUpdate", noop)
+	for _, kind := range []string{"customer-import", "batch.DelinquencyUpdate", "customer-import"} {
+		_, err := runner.Submit(context.Background(), jobs.Spec{Kind: kind})
+		require.NoError(t, err)
+	}
+	list := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.NewJobHandler(runner, logger).ListJobs(rec, httptest.NewRequest(http.MethodGet, "/jobs"+query, nil))
+		return rec
+	}
+
+	t.Run("filters by kind and status", func(t *testing.T) {
+		rec := list("?kind=customer-import&status=QUEUED&limit=1")
+
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var resp []dto.JobResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		require.Len(t, resp, 1)
+		assert.Equal(t, "customer-import", resp[0].Kind)
+		assert.Equal(t, "QUEUED", resp[0].Status)
+	})
+
+	for name, query := range map[string]string{
+		"status": "?status=DONE",
+		"limit":  "?limit=0",
+	} {
+		t.Run("invalid "+name, func(t *testing.T) {
+			assert.Equal(t, http.StatusBadRequest, list(query).Code)
+		})
+	}
+}
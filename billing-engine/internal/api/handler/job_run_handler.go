@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"billing-engine/internal/api/handler/dto"
+	"billing-engine/internal/jobs"
+	"billing-engine/internal/pkg/apperrors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	defaultListJobRuns = 20
+	maxListJobRuns     = 100
+)
+
+// JobRunHandler reports on the runs of the scheduled batch jobs, which are
+// kept after the async jobs that carried them expire. recorder is nil when
+// runs are not recorded and lookups are refused.
+type JobRunHandler struct {
+	recorder *jobs.RunRecorder
+	logger   *slog.Logger
+}
+
+func NewJobRunHandler(recorder *jobs.RunRecorder, l *slog.Logger) *JobRunHandler {
+	if l == nil {
+		panic("logger cannot be nil")
+	}
+	return &JobRunHandler{recorder: recorder, logger: l.With("component", "JobRunHandler")}
+}
+
+// ListRuns handles GET /admin/jobs/{name}/runs
+// @Summary List the runs of a batch job
+// @Description Lists the latest runs of a scheduled batch job, newest first, such as DelinquencyUpdate or LoanSnapshot. Each run has its status, the items processed out of the total, how many of them failed with the first of their errors, and how long it took or has taken so far.
+// @Tags Admin
+// @Produce json
+// @Param name path string true "Batch job name, e.g. DelinquencyUpdate"
+// @Param limit query int false "Maximum number of runs, default 20, at most 100"
+// @Success 200 {array} dto.JobRunResponse
+// @Failure 400 {object} dto.ErrorResponse "Invalid limit"
+// @Failure 403 {object} dto.ErrorResponse "Caller is not an admin"
+// @Failure 404 {object} dto.ErrorResponse "Unknown batch job"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Failure 503 {object} dto.ErrorResponse "Background jobs are not configured"
+// @Router /admin/jobs/{name}/runs [get]
+// @Security BearerAuth
+func (h *JobRunHandler) ListRuns(w http.ResponseWriter, r *http.Request) {
+	if h.recorder == nil {
+		respondError(w, errJobsDisabled)
+		return
+	}
+	limit := defaultListJobRuns
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxListJobRuns {
+			respondError(w, fmt.Errorf("%w: limit must be between 1 and %d", apperrors.ErrInvalidArgument, maxListJobRuns))
+			return
+		}
+		limit = n
+	}
+	runs, err := h.recorder.List(r.Context(), chi.URLParam(r, "name"), limit)
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "Failed to list job runs", slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, dto.NewJobRunListResponse(runs, time.Now()))
+}
+
+// GetRun handles GET /admin/jobs/{name}/runs/{runID}
+// @Summary Get a run of a batch job
+// @Description Returns one run of a scheduled batch job. A RUNNING run shows the progress written at most a few seconds ago; a run whose instance stopped is closed as FAILED when the job is taken over.
+// @Tags Admin
+// @Produce json
+// @Param name path string true "Batch job name, e.g. DelinquencyUpdate"
+// @Param runID path int true "Run ID"
+// @Success 200 {object} dto.JobRunResponse
+// @Failure 400 {object} dto.ErrorResponse "Invalid run ID"
+// @Failure 403 {object} dto.ErrorResponse "Caller is not an admin"
+// @Failure 404 {object} dto.ErrorResponse "Unknown batch job or run"
+// @Failure 503 {object} dto.ErrorResponse "Background jobs are not configured"
+// @Router /admin/jobs/{name}/runs/{runID} [get]
+// @Security BearerAuth
+func (h *JobRunHandler) GetRun(w http.ResponseWriter, r *http.Request) {
+	if h.recorder == nil {
+		respondError(w, errJobsDisabled)
+		return
+	}
+	runID, err := strconv.ParseInt(chi.URLParam(r, "runID"), 10, 64)
+	if err != nil || runID < 1 {
+		respondError(w, fmt.Errorf("%w: invalid run ID", apperrors.ErrInvalidArgument))
+		return
+	}
+	run, err := h.recorder.Get(r.Context(), chi.URLParam(r, "name"), runID)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, dto.NewJobRunResponse(*run, time.Now()))
+}
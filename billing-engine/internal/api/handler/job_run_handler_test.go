@@ -0,0 +1,113 @@
+package handler_test
+
+import (
+	"billing-engine/internal/api/handler"
+	"billing-engine/internal/api/handler/dto"
+	"billing-engine/internal/jobs"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobRunHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	recorder := jobs.NewRunRecorder(jobs.NewMemoryRunStore(), nil, logger)
+	recorder.Register("LoanSnapshot")
+	for _, fail := range []bool{false, true} {
+		_ = recorder.Track(context.Background(), "DelinquencyUpdate", "job-1", func(ctx context.Context) error {
+			progress := jobs.ProgressFrom(ctx)
+			progress.SetTotal(3)
+			progress.Done(2)
+			if fail {
+				progress.Fail(errors.New("loan 3: connection reset"))
+				return errors.New("job completed with 1 errors")
+			}
+			progress.Done(1)
+			return nil
+		})
+	}
+	serve := func(h *handler.JobRunHandler, path string) *httptest.ResponseRecorder {
+		router := chi.NewRouter()
+		router.Get("/admin/jobs/{name}/runs", h.ListRuns)
+		router.Get("/admin/jobs/{name}/runs/{runID}", h.GetRun)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+	h := handler.NewJobRunHandler(recorder, logger)
+
+	t.Run("lists the runs newest first", func(t *testing.T) {
+		rec := serve(h, "/admin/jobs/DelinquencyUpdate/runs")
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		var runs []dto.JobRunResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &runs))
+		require.Len(t, runs, 2)
+		assert.Equal(t, "FAILED", runs[0].Status)
+		assert.Equal(t, 3, runs[0].Total)
+		assert.Equal(t, 3, runs[0].Processed)
+		assert.Equal(t, 1, runs[0].Failed)
+		assert.Equal(t, []string{"loan 3: connection reset"}, runs[0].ErrorSamples)
+		assert.Equal(t, "job completed with 1 errors", runs[0].Error)
+		assert.Equal(t, "SUCCEEDED", runs[1].Status)
+		assert.Empty(t, runs[1].ErrorSamples)
+		assert.NotNil(t, runs[1].FinishedAt)
+	})
+
+	t.Run("limits the list", func(t *testing.T) {
+		rec := serve(h, "/admin/jobs/DelinquencyUpdate/runs?limit=1")
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		var runs []dto.JobRunResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &runs))
+		assert.Len(t, runs, 1)
+
+		assert.Equal(t, http.StatusBadRequest, serve(h, "/admin/jobs/DelinquencyUpdate/runs?limit=1000").Code)
+	})
+
+	t.Run("a job that has not run yet", func(t *testing.T) {
+		rec := serve(h, "/admin/jobs/LoanSnapshot/runs")
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `[]`, rec.Body.String())
+	})
+
+	t.Run("gets one run", func(t *testing.T) {
+		runs, err := recorder.List(context.Background(), "DelinquencyUpdate", 1)
+		require.NoError(t, err)
+		id := strconv.FormatInt(runs[0].ID, 10)
+
+		rec := serve(h, "/admin/jobs/DelinquencyUpdate/runs/"+id)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		var run dto.JobRunResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &run))
+		assert.Equal(t, id, run.ID)
+		assert.Equal(t, "job-1", run.JobID)
+		assert.GreaterOrEqual(t, run.DurationSeconds, 0.0)
+
+		assert.Equal(t, http.StatusNotFound, serve(h, "/admin/jobs/LoanSnapshot/runs/"+id).Code, "runs are looked up under their job")
+	})
+
+	t.Run("unknown job or run", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, serve(h, "/admin/jobs/Nope/runs").Code)
+		assert.Equal(t, http.StatusNotFound, serve(h, "/admin/jobs/DelinquencyUpdate/runs/999").Code)
+		assert.Equal(t, http.StatusBadRequest, serve(h, "/admin/jobs/DelinquencyUpdate/runs/abc").Code)
+	})
+
+	t.Run("runs not recorded", func(t *testing.T) {
+		rec := serve(handler.NewJobRunHandler(nil, logger), "/admin/jobs/DelinquencyUpdate/runs")
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	})
+}
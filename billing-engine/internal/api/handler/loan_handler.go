@@ -4,15 +4,21 @@ import (
 	"billing-engine/internal/api/handler/dto"
 	"billing-engine/internal/domain/loan"
 	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/i18n"
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 )
 
@@ -28,16 +34,61 @@ func NewLoanHandler(s loan.LoanService, l *slog.Logger) *LoanHandler {
 	}
 }
 
+// maxJSONDepth bounds how deeply objects and arrays may nest in a request
+// body. No request DTO comes close; the limit keeps crafted payloads from
+// making the decoder recurse thousands of levels deep.
+const maxJSONDepth = 32
+
+// decodeJSON reads the whole body, which the body limit middleware keeps
+// bounded, and rejects it before decoding if it nests too deeply. A body
+// over the limit yields an error wrapping *http.MaxBytesError, which
+// respondError turns into 413.
 func decodeJSON(r *http.Request, v interface{}) error {
 	if r.Body == nil {
 		return fmt.Errorf("no request body")
 	}
 	defer r.Body.Close()
-	decoder := json.NewDecoder(r.Body)
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return fmt.Errorf("read request body: %w", err)
+	}
+	if err := checkJSONDepth(data, maxJSONDepth); err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
 	decoder.DisallowUnknownFields()
 	return decoder.Decode(v)
 }
 
+// checkJSONDepth scans data without decoding it. Brackets inside strings are
+// skipped; anything else malformed is left for the decoder to report.
+func checkJSONDepth(data []byte, max int) error {
+	depth, inString, escaped := 0, false, false
+	for _, c := range data {
+		switch {
+		case inString:
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '{' || c == '[':
+			depth++
+			if depth > max {
+				return fmt.Errorf("JSON nesting exceeds %d levels", max)
+			}
+		case c == '}' || c == ']':
+			depth--
+		}
+	}
+	return nil
+}
+
 func respondJSON(w http.ResponseWriter, status int, payload interface{}) {
 	response, err := json.Marshal(payload)
 	if err != nil {
@@ -50,66 +101,81 @@ func respondJSON(w http.ResponseWriter, status int, payload interface{}) {
 	w.Write(response)
 }
 
+// respondError maps err to its status and body. The fixed messages follow the
+// locale the Locale middleware put in Content-Language; messages carrying the
+// error's own text stay in English.
 func respondError(w http.ResponseWriter, err error) {
-	status, message, field := http.StatusInternalServerError, "An unexpected error occurred.", ""
+	locale := w.Header().Get("Content-Language")
+	m := apperrors.Lookup(err)
+	status := m.HTTPStatus
+	detail := dto.ErrorDetail{Code: string(m.Code), Message: i18n.ErrorMessage(locale, err), Retryable: m.Retryable}
+	if m.Public {
+		detail.Details = apperrors.Metadata(err)
+	}
 	var validationError *apperrors.ValidationError
-	var appErr *apperrors.AppError
+	var paymentErr *apperrors.PaymentAmountError
+	var maxBytesErr *http.MaxBytesError
 
 	switch {
-	case errors.Is(err, apperrors.ErrNotFound):
-		status, message = http.StatusNotFound, "Resource not found."
-	case errors.Is(err, apperrors.ErrInvalidArgument), errors.Is(err, apperrors.ErrValidation):
-		status, message = http.StatusBadRequest, err.Error()
-	case errors.Is(err, apperrors.ErrInvalidPaymentAmount), errors.Is(err, apperrors.ErrLoanFullyPaid):
-		status, message = http.StatusBadRequest, err.Error()
+	case errors.As(err, &maxBytesErr):
+		status = http.StatusRequestEntityTooLarge
+		detail = dto.ErrorDetail{Code: string(apperrors.CodeInvalidArgument), Message: i18n.Message(locale, i18n.MsgBodyTooLarge, maxBytesErr.Limit)}
+	case errors.As(err, &paymentErr):
+		detail.ExpectedAmount = paymentErr.ExpectedString()
 	case errors.As(err, &validationError):
-		status, message, field = http.StatusBadRequest, validationError.Message, validationError.Field
-	case errors.As(err, &appErr):
-		message = appErr.Error()
-	default:
+		detail.Field = validationError.Field
+	case status == http.StatusInternalServerError:
 		slog.Default().Error("Unhandled internal error", "error", err)
 	}
 
-	resp := dto.ErrorResponse{
-		Error: dto.ErrorDetail{
-			Message: message,
-			Field:   field,
-		},
-	}
-	respondJSON(w, status, resp)
+	respondJSON(w, status, dto.ErrorResponse{Error: detail})
 }
 
-func getLoanIDFromURL(r *http.Request) (int64, error) {
+// loanIDFromURL accepts either the numeric loan ID or the loan's public UUID
+// and returns the internal ID. Errors are already classified for respondError.
+func (h *LoanHandler) loanIDFromURL(r *http.Request) (int64, error) {
 	idStr := chi.URLParam(r, "loanID")
 	if idStr == "" {
-		return 0, fmt.Errorf("loanID not found in URL path")
+		return 0, fmt.Errorf("%w: loanID not found in URL path", apperrors.ErrInvalidArgument)
+	}
+	if publicID, err := uuid.Parse(idStr); err == nil {
+		return h.service.ResolveLoanID(r.Context(), publicID)
+	}
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err)
 	}
-	return strconv.ParseInt(idStr, 10, 64)
+	return id, nil
 }
 
 // CreateLoan handles the creation of a new loan.
 //
 // @Summary Create a new loan
-// @Description This endpoint allows the creation of a new loan by providing the principal amount, term in weeks, annual interest rate, and start date.
+// @Description This endpoint allows the creation of a new loan by providing the principal amount, term in weeks, annual interest rate, and start date. An optional product, one of those configured under `products`, books an interest-only or balloon schedule instead of a standard one; the total repaid is the same.
 // @Tags Loans
 // @Accept json
 // @Produce json
 // @Param request body dto.CreateLoanRequest true "Loan creation request payload"
 // @Success 201 {object} dto.LoanResponse "Loan successfully created"
-// @Failure 400 {object} dto.ErrorResponse "Invalid request payload or validation error"
+// @Failure 400 {object} dto.ErrorResponse "Invalid request payload or validation error, an unknown product, or a product whose schedule does not fit the term"
+// @Failure 409 {object} dto.ErrorResponse "Customer already has a loan that is not paid off, or the external reference or public ID is taken"
 // @Failure 500 {object} dto.ErrorResponse "Internal server error"
 // @Router /loans [post]
 // @Security BearerAuth
 func (h *LoanHandler) CreateLoan(w http.ResponseWriter, r *http.Request) {
 	var req dto.CreateLoanRequest
-	if err := decodeJSON(r, &req); err != nil || req.Validate() != nil {
+	if err := decodeJSON(r, &req); err != nil {
+		respondError(w, fmt.Errorf("%w: %w", apperrors.ErrInvalidArgument, err))
+		return
+	}
+	if err := req.Validate(); err != nil {
 		respondError(w, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err))
 		return
 	}
 
 	startDate, _ := time.Parse(time.RFC3339[:10], req.StartDate)
 
-	createdLoan, err := h.service.CreateLoan(r.Context(), req.CustomerID, req.Principal, req.TermWeeks, req.AnnualInterestRate, startDate)
+	createdLoan, err := h.service.CreateLoan(r.Context(), req.CustomerID, req.Principal, req.TermWeeks, req.AnnualInterestRate, startDate, req.ExternalRef, req.PublicUUID(), req.Product)
 	if err != nil {
 		respondError(w, err)
 		return
@@ -127,61 +193,221 @@ func (h *LoanHandler) CreateLoan(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param loanID path int true "Loan ID"
 // @Param include query string false "Optional parameter to include repayment schedule (use 'schedule')"
+// @Param If-None-Match header string false "ETag from an earlier response"
+// @Param If-Modified-Since header string false "Last-Modified value from an earlier response, ignored when If-None-Match is sent"
 // @Success 200 {object} dto.LoanResponse "Loan details successfully retrieved"
+// @Header 200 {string} ETag "Changes whenever the loan, its schedule or its hold changes"
+// @Header 200 {string} Last-Modified "When the loan, its schedule or its hold last changed"
+// @Success 304 "Loan unchanged since the ETag or date sent"
 // @Failure 400 {object} dto.ErrorResponse "Invalid loan ID or request parameters"
 // @Failure 404 {object} dto.ErrorResponse "Loan not found"
 // @Failure 500 {object} dto.ErrorResponse "Internal server error"
 // @Router /loans/{loanID} [get]
 // @Security BearerAuth
 func (h *LoanHandler) GetLoan(w http.ResponseWriter, r *http.Request) {
-	loanID, err := getLoanIDFromURL(r)
+	loanID, err := h.loanIDFromURL(r)
 	if err != nil {
-		respondError(w, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err))
+		respondError(w, err)
 		return
 	}
 
-	domainLoan, err := h.service.GetLoan(r.Context(), loanID)
+	includeSchedule := r.URL.Query().Get("include") == "schedule"
+	domainLoan, err := h.service.GetLoan(r.Context(), loanID, includeSchedule)
 	if err != nil {
 		respondError(w, err)
 		return
 	}
 
-	includeSchedule := r.URL.Query().Get("include") == "schedule"
+	if notModified(w, r, domainLoan.LastModified()) {
+		return
+	}
 	resp := dto.NewLoanResponse(domainLoan, includeSchedule)
-	respondJSON(w, http.StatusOK, resp)
+	respondCacheableJSON(w, r, resp)
+}
+
+// FindLoanByExternalRef looks a loan up by the reference supplied by the
+// integrator when it was created. Without a reference, a client accepting
+// NDJSON gets every loan instead.
+//
+// @Summary Find loan by external reference
+// @Description Retrieves the loan created with the given external reference. Add `include=schedule` to include the repayment schedule. Without `external_ref` and with `Accept: application/x-ndjson`, streams every loan in ID order, one per line and without schedules; pass the `id` of the last line received as `cursor` to resume, `dpd_gte` to stream only loans at least that many days past due, and `status` to stream only loans in that status.
+// @Tags Loans
+// @Produce json
+// @Produce x-ndjson
+// @Param external_ref query string false "External reference supplied on creation, required unless streaming"
+// @Param include query string false "Optional parameter to include repayment schedule (use 'schedule')"
+// @Param cursor query int false "Stream only loans with an ID above this one"
+// @Param dpd_gte query int false "Stream only loans at least this many days past due"
+// @Param status query string false "Stream only loans in this status (ACTIVE, DELINQUENT or PAID_OFF)"
+// @Success 200 {object} dto.LoanResponse "Loan details successfully retrieved"
+// @Failure 400 {object} dto.ErrorResponse "Missing external_ref query parameter, or invalid cursor, dpd_gte or status"
+// @Failure 404 {object} dto.ErrorResponse "Loan not found"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /loans [get]
+// @Security BearerAuth
+func (h *LoanHandler) FindLoanByExternalRef(w http.ResponseWriter, r *http.Request) {
+	externalRef := strings.TrimSpace(r.URL.Query().Get("external_ref"))
+	if externalRef == "" && wantsNDJSON(r) {
+		h.streamLoans(w, r)
+		return
+	}
+	if externalRef == "" {
+		respondError(w, fmt.Errorf("%w: missing required query parameter 'external_ref'", apperrors.ErrInvalidArgument))
+		return
+	}
+
+	includeSchedule := r.URL.Query().Get("include") == "schedule"
+	domainLoan, err := h.service.GetLoanByExternalRef(r.Context(), externalRef, includeSchedule)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, dto.NewLoanResponse(domainLoan, includeSchedule))
+}
+
+// SearchLoans handles GET /loans/search
+// @Summary Search loans by payment or customer
+// @Description Finds loans from what a customer can tell collections: the reference of a payment or prepayment, roughly how much was paid and when, or the customer's name. Criteria given together must all match. A search by amount needs `from` and `to`, at most 31 days apart, and matches payments within `tolerance` of the amount, 1% of it by default. A name matches any part of the customer's name, ignoring case. Matches by payment come newest first, one per matching payment; a search by name alone returns the customers' loans by name, without payments.
+// @Tags Loans
+// @Produce json
+// @Param reference query string false "Payment or prepayment reference, exact"
+// @Param amount query number false "Amount paid"
+// @Param tolerance query number false "How far the paid amount may be from amount"
+// @Param from query string false "First day paid, YYYY-MM-DD"
+// @Param to query string false "Last day paid, YYYY-MM-DD"
+// @Param customer_name query string false "Part of the customer's name, at least 3 characters"
+// @Param limit query int false "Maximum number of matches, default 20, at most 100"
+// @Success 200 {array} dto.LoanSearchMatchResponse
+// @Failure 400 {object} dto.ErrorResponse "No criteria, or an invalid amount, window, name or limit"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /loans/search [get]
+// @Security BearerAuth
+func (h *LoanHandler) SearchLoans(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	search := loan.LoanSearch{
+		Reference:    query.Get("reference"),
+		CustomerName: query.Get("customer_name"),
+	}
+	var err error
+	if search.Amount, err = moneyQueryParam(r, "amount"); err != nil {
+		respondError(w, err)
+		return
+	}
+	if search.Tolerance, err = moneyQueryParam(r, "tolerance"); err != nil {
+		respondError(w, err)
+		return
+	}
+	if search.From, err = dateQueryParam(r, "from", time.Time{}); err != nil {
+		respondError(w, err)
+		return
+	}
+	if search.To, err = dateQueryParam(r, "to", time.Time{}); err != nil {
+		respondError(w, err)
+		return
+	}
+	if !search.To.IsZero() {
+		search.To = search.To.AddDate(0, 0, 1)
+	}
+	if raw := query.Get("limit"); raw != "" {
+		if search.Limit, err = strconv.Atoi(raw); err != nil || search.Limit < 1 {
+			respondError(w, fmt.Errorf("%w: limit must be between 1 and %d", apperrors.ErrInvalidArgument, loan.MaxSearchLimit))
+			return
+		}
+	}
+
+	matches, err := h.service.SearchLoans(r.Context(), search)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, dto.NewLoanSearchResponse(matches))
+}
+
+// moneyQueryParam reads an optional amount; nil means it was not given.
+func moneyQueryParam(r *http.Request, name string) (*loan.Money, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return nil, nil
+	}
+	amount, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s must be a number", apperrors.ErrInvalidArgument, name)
+	}
+	return &amount, nil
+}
+
+func (h *LoanHandler) streamLoans(w http.ResponseWriter, r *http.Request) {
+	cursor, err := cursorQueryParam(r)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	filter := loan.LoanFilter{AfterID: cursor}
+	if raw := r.URL.Query().Get("dpd_gte"); raw != "" {
+		dpd, err := strconv.Atoi(raw)
+		if err != nil || dpd < 0 {
+			respondError(w, fmt.Errorf("%w: dpd_gte must be a non-negative integer", apperrors.ErrInvalidArgument))
+			return
+		}
+		filter.MinDaysPastDue = dpd
+	}
+	if raw := r.URL.Query().Get("status"); raw != "" {
+		status := loan.LoanStatus(strings.ToUpper(raw))
+		switch status {
+		case loan.StatusActive, loan.StatusDelinquent, loan.StatusPaidOff:
+			filter.Status = status
+		default:
+			respondError(w, fmt.Errorf("%w: status must be ACTIVE, DELINQUENT or PAID_OFF", apperrors.ErrInvalidArgument))
+			return
+		}
+	}
+	streamNDJSON(w, r, h.logger, func(ctx context.Context, emit func(any) error) error {
+		return h.service.StreamLoans(ctx, filter, func(l *loan.Loan) error {
+			return emit(dto.NewLoanResponse(l, false))
+		})
+	})
 }
 
 // GetOutstanding retrieves the outstanding amount for a specific loan.
 //
 // @Summary Retrieve outstanding loan amount
-// @Description This endpoint retrieves the outstanding amount for a loan by its ID.
+// @Description This endpoint retrieves the outstanding amount for a loan by its ID, itemized into unpaid installments (split into principal and interest), fees, penalties, suspense and credits.
 // @Tags Loans
 // @Produce json
 // @Param loanID path int true "Loan ID"
+// @Param If-Modified-Since header string false "Last-Modified value from an earlier response"
 // @Success 200 {object} dto.OutstandingResponse "Outstanding amount successfully retrieved"
+// @Header 200 {string} Last-Modified "When the loan or its schedule last changed"
+// @Success 304 "Nothing changed since If-Modified-Since"
 // @Failure 400 {object} dto.ErrorResponse "Invalid loan ID or request parameters"
 // @Failure 404 {object} dto.ErrorResponse "Loan not found"
 // @Failure 500 {object} dto.ErrorResponse "Internal server error"
 // @Router /loans/{loanID}/outstanding [get]
 // @Security BearerAuth
 func (h *LoanHandler) GetOutstanding(w http.ResponseWriter, r *http.Request) {
-	loanID, err := getLoanIDFromURL(r)
+	loanID, err := h.loanIDFromURL(r)
 	if err != nil {
-		respondError(w, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err))
+		respondError(w, err)
 		return
 	}
 
-	outstandingAmountFloat, err := h.service.GetOutstanding(r.Context(), loanID)
+	lastModified, err := h.service.GetLastModified(r.Context(), loanID)
 	if err != nil {
 		respondError(w, err)
 		return
 	}
+	if notModified(w, r, lastModified) {
+		return
+	}
 
-	resp := dto.OutstandingResponse{
-		LoanID:            strconv.FormatInt(loanID, 10),
-		OutstandingAmount: fmt.Sprintf("%.2f", outstandingAmountFloat),
+	breakdown, err := h.service.GetOutstandingBreakdown(r.Context(), loanID)
+	if err != nil {
+		respondError(w, err)
+		return
 	}
-	respondJSON(w, http.StatusOK, resp)
+
+	respondJSON(w, http.StatusOK, dto.NewOutstandingResponse(breakdown))
 }
 
 // IsDelinquent checks if a loan is delinquent.
@@ -198,9 +424,9 @@ func (h *LoanHandler) GetOutstanding(w http.ResponseWriter, r *http.Request) {
 // @Router /loans/{loanID}/delinquent [get]
 // @Security BearerAuth
 func (h *LoanHandler) IsDelinquent(w http.ResponseWriter, r *http.Request) {
-	loanID, err := getLoanIDFromURL(r)
+	loanID, err := h.loanIDFromURL(r)
 	if err != nil {
-		respondError(w, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err))
+		respondError(w, err)
 		return
 	}
 
@@ -220,7 +446,7 @@ func (h *LoanHandler) IsDelinquent(w http.ResponseWriter, r *http.Request) {
 // MakePayment processes a payment for a specific loan.
 //
 // @Summary Make a loan payment
-// @Description This endpoint processes a payment for a loan by its ID. The payment amount must be specified in the request payload.
+// @Description This endpoint processes a payment for a loan by its ID. The payment amount must be specified in the request payload. The optional channel, reference and collector ID are recorded in the payments ledger for reconciliation; a reference already posted through the same channel is rejected.
 // @Tags Loans
 // @Accept json
 // @Produce json
@@ -229,18 +455,23 @@ func (h *LoanHandler) IsDelinquent(w http.ResponseWriter, r *http.Request) {
 // @Success 200 {object} map[string]string "Payment successfully processed"
 // @Failure 400 {object} dto.ErrorResponse "Invalid loan ID, request payload, or validation error"
 // @Failure 404 {object} dto.ErrorResponse "Loan not found"
+// @Failure 409 {object} dto.ErrorResponse "Reference already posted through the channel, another payment for the loan is in progress, or the loan is on hold"
 // @Failure 500 {object} dto.ErrorResponse "Internal server error"
 // @Router /loans/{loanID}/payments [post]
 // @Security BearerAuth
 func (h *LoanHandler) MakePayment(w http.ResponseWriter, r *http.Request) {
-	loanID, err := getLoanIDFromURL(r)
+	loanID, err := h.loanIDFromURL(r)
 	if err != nil {
-		respondError(w, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err))
+		respondError(w, err)
 		return
 	}
 
 	var req dto.MakePaymentRequest
-	if err := decodeJSON(r, &req); err != nil || req.Validate() != nil {
+	if err := decodeJSON(r, &req); err != nil {
+		respondError(w, fmt.Errorf("%w: %w", apperrors.ErrInvalidArgument, err))
+		return
+	}
+	if err := req.Validate(); err != nil {
 		respondError(w, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err))
 		return
 	}
@@ -252,10 +483,500 @@ func (h *LoanHandler) MakePayment(w http.ResponseWriter, r *http.Request) {
 	}
 	amountFloat, _ := amountDecimal.Float64()
 
-	if err := h.service.MakePayment(r.Context(), loanID, amountFloat); err != nil {
+	if err := h.service.MakePayment(r.Context(), loanID, amountFloat, req.Details()); err != nil {
 		respondError(w, err)
 		return
 	}
 
 	respondJSON(w, http.StatusOK, map[string]string{"message": "Payment successful"})
 }
+
+// Prepay handles POST /loans/{loanID}/prepayments.
+// @Summary Prepay part of a loan's principal
+// @Description Takes a lump sum on top of the installments and reamortizes the unpaid ones in the same transaction. REDUCE_TERM keeps the installment and drops the weeks the prepaid principal no longer needs, shortening the last; REDUCE_INSTALLMENT keeps the term and spreads what is left over the remaining weeks. Interest is charged on the principal each installment repays, so both lower the loan total. The prepayment is recorded with the option chosen, the terms before and after, and the channel and reference, which are unique per channel as for payments; GET /loans/{loanID} lists it. The loan must be current, with no installment overdue, and the lump sum must be less than the principal outstanding.
+// @Tags Loans
+// @Accept json
+// @Produce json
+// @Param loanID path int true "Loan ID"
+// @Param request body dto.PrepaymentRequest true "Amount, option, channel and reference"
+// @Success 201 {object} dto.ReamortizationResponse "Prepayment recorded and schedule reamortized"
+// @Failure 400 {object} dto.ErrorResponse "Invalid loan ID, amount, option or channel, or an amount that would settle the loan"
+// @Failure 404 {object} dto.ErrorResponse "Loan not found"
+// @Failure 409 {object} dto.ErrorResponse "Loan is paid off, delinquent, overdue or on hold, has an interest-only or balloon schedule, the reference was already posted through the channel, or a payment is in progress"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /loans/{loanID}/prepayments [post]
+// @Security BearerAuth
+func (h *LoanHandler) Prepay(w http.ResponseWriter, r *http.Request) {
+	loanID, err := h.loanIDFromURL(r)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	var req dto.PrepaymentRequest
+	if err := decodeJSON(r, &req); err != nil {
+		respondError(w, fmt.Errorf("%w: %w", apperrors.ErrInvalidArgument, err))
+		return
+	}
+	if err := req.Validate(); err != nil {
+		respondError(w, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err))
+		return
+	}
+	amount, _ := decimal.RequireFromString(req.Amount).Float64()
+
+	plan, err := h.service.Prepay(r.Context(), loanID, amount, loan.PrepaymentOption(req.Option), req.Details(), actorFromContext(r.Context()))
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, dto.NewReamortizationResponse(plan))
+}
+
+// PreviewRestructure handles POST /loans/{loanID}/restructure/preview.
+// @Summary Preview a restructure of a loan
+// @Description Works out what a repricing (REPRICING), payment holiday (PAYMENT_HOLIDAY), zero-interest window (ZERO_INTEREST) or prepayment (PREPAYMENT) would do to the loan without making it: the installments it would add, recalculate, move or drop, and the rate, term, installment, total, total interest and date of the last installment before and after. Only the fields of the kind are read. A preview is refused for the same reasons the change would be, so a 409 here means POST /loans/{loanID}/rate, /adjustments or /prepayments would fail too. Nothing is stored.
+// @Tags Loans
+// @Accept json
+// @Produce json
+// @Param loanID path int true "Loan ID"
+// @Param request body dto.RestructurePreviewRequest true "Kind and the fields of the change"
+// @Success 200 {object} dto.RestructurePreviewResponse "What the restructure would change"
+// @Failure 400 {object} dto.ErrorResponse "Invalid loan ID, kind or change, or a change that touches no installment"
+// @Failure 404 {object} dto.ErrorResponse "Loan not found"
+// @Failure 409 {object} dto.ErrorResponse "The change could not be made to the loan, or a payment is in progress"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /loans/{loanID}/restructure/preview [post]
+// @Security BearerAuth
+func (h *LoanHandler) PreviewRestructure(w http.ResponseWriter, r *http.Request) {
+	loanID, err := h.loanIDFromURL(r)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	var req dto.RestructurePreviewRequest
+	if err := decodeJSON(r, &req); err != nil {
+		respondError(w, fmt.Errorf("%w: %w", apperrors.ErrInvalidArgument, err))
+		return
+	}
+	if err := req.Validate(); err != nil {
+		respondError(w, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err))
+		return
+	}
+
+	preview, err := h.service.PreviewRestructure(r.Context(), loanID, req.Restructure())
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, dto.NewRestructurePreviewResponse(preview))
+}
+
+// ListFeeTypes handles GET /loans/fee-types.
+// @Summary List the fee catalog
+// @Description Lists the fee types that can be posted to a loan.
+// @Tags Loans
+// @Produce json
+// @Success 200 {array} dto.FeeTypeResponse "Fee types"
+// @Router /loans/fee-types [get]
+// @Security BearerAuth
+func (h *LoanHandler) ListFeeTypes(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, dto.NewFeeTypesResponse(loan.FeeCatalog()))
+}
+
+// PostFee handles POST /loans/{loanID}/fees.
+// @Summary Post a fee to a loan
+// @Description Charges the loan an ad-hoc fee of a catalog type, such as a bounce fee after a returned direct debit. The fee is owed on top of the installments and shows in the outstanding breakdown until a waiver of it is approved. The token's username is recorded as postedBy.
+// @Tags Loans
+// @Accept json
+// @Produce json
+// @Param loanID path int true "Loan ID"
+// @Param request body dto.PostFeeRequest true "Fee type, amount and reason"
+// @Success 201 {object} dto.FeeResponse "Fee posted"
+// @Failure 400 {object} dto.ErrorResponse "Invalid loan ID, fee type or amount, or missing reason"
+// @Failure 404 {object} dto.ErrorResponse "Loan not found"
+// @Failure 409 {object} dto.ErrorResponse "Loan is paid off"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /loans/{loanID}/fees [post]
+// @Security BearerAuth
+func (h *LoanHandler) PostFee(w http.ResponseWriter, r *http.Request) {
+	loanID, err := h.loanIDFromURL(r)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	var req dto.PostFeeRequest
+	if err := decodeJSON(r, &req); err != nil {
+		respondError(w, fmt.Errorf("%w: %w", apperrors.ErrInvalidArgument, err))
+		return
+	}
+	if err := req.Validate(); err != nil {
+		respondError(w, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err))
+		return
+	}
+	amount, _ := decimal.RequireFromString(req.Amount).Float64()
+
+	fee, err := h.service.PostFee(r.Context(), loanID, loan.FeeType(req.Type), amount, req.Reason, actorFromContext(r.Context()))
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, dto.NewFeeResponse(fee))
+}
+
+// GetFees handles GET /loans/{loanID}/fees.
+// @Summary List a loan's fees
+// @Description Lists the fees posted to the loan, oldest first, each with its waiver requests and their decisions.
+// @Tags Loans
+// @Produce json
+// @Param loanID path int true "Loan ID"
+// @Success 200 {array} dto.FeeResponse "Fees"
+// @Failure 400 {object} dto.ErrorResponse "Invalid loan ID"
+// @Failure 404 {object} dto.ErrorResponse "Loan not found"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /loans/{loanID}/fees [get]
+// @Security BearerAuth
+func (h *LoanHandler) GetFees(w http.ResponseWriter, r *http.Request) {
+	loanID, err := h.loanIDFromURL(r)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	fees, err := h.service.GetFees(r.Context(), loanID)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, dto.NewFeesResponse(fees))
+}
+
+// RequestFeeWaiver handles POST /loans/{loanID}/fees/{feeID}/waiver.
+// @Summary Request a fee waiver
+// @Description Asks for the fee to be written off. The fee stays owed until an admin other than the requester approves the request; a fee has at most one request pending or approved.
+// @Tags Loans
+// @Accept json
+// @Produce json
+// @Param loanID path int true "Loan ID"
+// @Param feeID path int true "Fee ID"
+// @Param request body dto.FeeWaiverRequest true "Reason for the waiver"
+// @Success 201 {object} dto.FeeWaiverResponse "Waiver requested"
+// @Failure 400 {object} dto.ErrorResponse "Invalid loan or fee ID, or missing reason"
+// @Failure 403 {object} dto.ErrorResponse "Token has no username to record as the requester"
+// @Failure 404 {object} dto.ErrorResponse "Loan has no such fee"
+// @Failure 409 {object} dto.ErrorResponse "Fee is already paid or waived, or has a waiver awaiting a decision"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /loans/{loanID}/fees/{feeID}/waiver [post]
+// @Security BearerAuth
+func (h *LoanHandler) RequestFeeWaiver(w http.ResponseWriter, r *http.Request) {
+	loanID, err := h.loanIDFromURL(r)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	feeID, err := int64URLParam(r, "feeID")
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	var req dto.FeeWaiverRequest
+	if err := decodeJSON(r, &req); err != nil {
+		respondError(w, fmt.Errorf("%w: %w", apperrors.ErrInvalidArgument, err))
+		return
+	}
+	if err := req.Validate(); err != nil {
+		respondError(w, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err))
+		return
+	}
+
+	waiver, err := h.service.RequestFeeWaiver(r.Context(), loanID, feeID, req.Reason, actorFromContext(r.Context()))
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, dto.NewFeeWaiverResponse(waiver))
+}
+
+// ApproveFeeWaiver handles POST /loans/{loanID}/fees/{feeID}/waiver/approve.
+// @Summary Approve a fee waiver
+// @Description Approves the fee's pending waiver, which takes the fee out of the outstanding amount. The approver is recorded and must not be the requester.
+// @Tags Loans
+// @Produce json
+// @Param loanID path int true "Loan ID"
+// @Param feeID path int true "Fee ID"
+// @Success 200 {object} dto.FeeWaiverResponse "Waiver approved"
+// @Failure 400 {object} dto.ErrorResponse "Invalid loan or fee ID"
+// @Failure 403 {object} dto.ErrorResponse "Token lacks the admin scope, has no username or belongs to the requester, or the waiver has no recorded requester"
+// @Failure 404 {object} dto.ErrorResponse "Loan has no such fee, or it has no pending waiver"
+// @Failure 409 {object} dto.ErrorResponse "The waiver was decided meanwhile"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /loans/{loanID}/fees/{feeID}/waiver/approve [post]
+// @Security BearerAuth
+func (h *LoanHandler) ApproveFeeWaiver(w http.ResponseWriter, r *http.Request) {
+	h.decideFeeWaiver(w, r, true)
+}
+
+// RejectFeeWaiver handles POST /loans/{loanID}/fees/{feeID}/waiver/reject.
+// @Summary Reject a fee waiver
+// @Description Rejects the fee's pending waiver. The fee stays owed and a new waiver can be requested.
+// @Tags Loans
+// @Produce json
+// @Param loanID path int true "Loan ID"
+// @Param feeID path int true "Fee ID"
+// @Success 200 {object} dto.FeeWaiverResponse "Waiver rejected"
+// @Failure 400 {object} dto.ErrorResponse "Invalid loan or fee ID"
+// @Failure 403 {object} dto.ErrorResponse "Token lacks the admin scope, has no username or belongs to the requester, or the waiver has no recorded requester"
+// @Failure 404 {object} dto.ErrorResponse "Loan has no such fee, or it has no pending waiver"
+// @Failure 409 {object} dto.ErrorResponse "The waiver was decided meanwhile"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /loans/{loanID}/fees/{feeID}/waiver/reject [post]
+// @Security BearerAuth
+func (h *LoanHandler) RejectFeeWaiver(w http.ResponseWriter, r *http.Request) {
+	h.decideFeeWaiver(w, r, false)
+}
+
+func (h *LoanHandler) decideFeeWaiver(w http.ResponseWriter, r *http.Request, approve bool) {
+	loanID, err := h.loanIDFromURL(r)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	feeID, err := int64URLParam(r, "feeID")
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	waiver, err := h.service.DecideFeeWaiver(r.Context(), loanID, feeID, approve, actorFromContext(r.Context()))
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, dto.NewFeeWaiverResponse(waiver))
+}
+
+// PlaceHold handles POST /loans/{loanID}/hold.
+// @Summary Place a loan on hold
+// @Description Places an administrative hold on the loan. Until it is released the loan accepts no payments and its installments are left out of direct-debit collection. Staff see the open hold in the loan response.
+// @Tags Loans
+// @Accept json
+// @Produce json
+// @Param loanID path int true "Loan ID"
+// @Param request body dto.PlaceHoldRequest true "Reason for the hold"
+// @Success 201 {object} dto.LoanHoldResponse "Hold placed"
+// @Failure 400 {object} dto.ErrorResponse "Invalid loan ID or missing reason"
+// @Failure 403 {object} dto.ErrorResponse "Token lacks the admin scope"
+// @Failure 404 {object} dto.ErrorResponse "Loan not found"
+// @Failure 409 {object} dto.ErrorResponse "Loan is already on hold"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /loans/{loanID}/hold [post]
+// @Security BearerAuth
+func (h *LoanHandler) PlaceHold(w http.ResponseWriter, r *http.Request) {
+	loanID, err := h.loanIDFromURL(r)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	var req dto.PlaceHoldRequest
+	if err := decodeJSON(r, &req); err != nil {
+		respondError(w, fmt.Errorf("%w: %w", apperrors.ErrInvalidArgument, err))
+		return
+	}
+	if err := req.Validate(); err != nil {
+		respondError(w, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err))
+		return
+	}
+
+	hold, err := h.service.PlaceHold(r.Context(), loanID, req.Reason, actorFromContext(r.Context()))
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, dto.NewLoanHoldResponse(hold))
+}
+
+// ReleaseHold handles DELETE /loans/{loanID}/hold.
+// @Summary Release a loan hold
+// @Description Releases the loan's open hold, after which payments and direct-debit collection resume.
+// @Tags Loans
+// @Produce json
+// @Param loanID path int true "Loan ID"
+// @Success 200 {object} dto.LoanHoldResponse "Released hold"
+// @Failure 400 {object} dto.ErrorResponse "Invalid loan ID"
+// @Failure 403 {object} dto.ErrorResponse "Token lacks the admin scope"
+// @Failure 404 {object} dto.ErrorResponse "Loan not found or not on hold"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /loans/{loanID}/hold [delete]
+// @Security BearerAuth
+func (h *LoanHandler) ReleaseHold(w http.ResponseWriter, r *http.Request) {
+	loanID, err := h.loanIDFromURL(r)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	hold, err := h.service.ReleaseHold(r.Context(), loanID, actorFromContext(r.Context()))
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, dto.NewLoanHoldResponse(hold))
+}
+
+// RepriceLoan handles POST /loans/{loanID}/rate.
+// @Summary Change a loan's interest rate
+// @Description Charges a new rate on the installments due on or after effectiveFrom and recalculates them; earlier installments keep their amount. The change is added to the loan's rate history, which GET /loans/{loanID} returns. Installments from effectiveFrom on must be unpaid, and a loan's rate changes must take effect in the order they are made.
+// @Tags Loans
+// @Accept json
+// @Produce json
+// @Param loanID path int true "Loan ID"
+// @Param request body dto.RepriceLoanRequest true "New rate and the date it takes effect"
+// @Success 201 {object} dto.RepricingResponse "Rate changed"
+// @Failure 400 {object} dto.ErrorResponse "Invalid loan ID, rate or date, or no installment due from that date"
+// @Failure 403 {object} dto.ErrorResponse "Token lacks the admin scope"
+// @Failure 404 {object} dto.ErrorResponse "Loan not found"
+// @Failure 409 {object} dto.ErrorResponse "Loan is paid off, has an interest-only or balloon schedule, already charged the rate, repriced from a later date or paid past the date, or a payment is in progress"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /loans/{loanID}/rate [post]
+// @Security BearerAuth
+func (h *LoanHandler) RepriceLoan(w http.ResponseWriter, r *http.Request) {
+	loanID, err := h.loanIDFromURL(r)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	var req dto.RepriceLoanRequest
+	if err := decodeJSON(r, &req); err != nil {
+		respondError(w, fmt.Errorf("%w: %w", apperrors.ErrInvalidArgument, err))
+		return
+	}
+	if err := req.Validate(); err != nil {
+		respondError(w, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err))
+		return
+	}
+
+	repricing, err := h.service.RepriceLoan(r.Context(), loanID, req.AnnualInterestRate, req.EffectiveDate(), actorFromContext(r.Context()))
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, dto.NewRepricingResponse(repricing))
+}
+
+// ApplyScheduleAdjustment handles POST /loans/{loanID}/adjustments.
+// @Summary Grant a payment holiday or zero-interest window
+// @Description A PAYMENT_HOLIDAY moves every installment due on or after startsOn back by weeks weeks, extending the term. A ZERO_INTEREST window waives the interest of the installments due in the weeks from startsOn and lowers the loan total. The adjustment is recorded with who applied it and listed by GET /loans/{loanID}. It cannot start before today, overlap another adjustment of its kind or move a paid installment.
+// @Tags Loans
+// @Accept json
+// @Produce json
+// @Param loanID path int true "Loan ID"
+// @Param request body dto.ApplyAdjustmentRequest true "Kind, start date, weeks and reason"
+// @Success 201 {object} dto.AdjustmentPlanResponse "Adjustment applied"
+// @Failure 400 {object} dto.ErrorResponse "Invalid loan ID or adjustment, or an adjustment that changes no installment"
+// @Failure 403 {object} dto.ErrorResponse "Token lacks the admin scope"
+// @Failure 404 {object} dto.ErrorResponse "Loan not found"
+// @Failure 409 {object} dto.ErrorResponse "Loan is paid off, delinquent (payment holidays only), has an interest-only or balloon schedule, already adjusted over those weeks, paid in them, or a payment is in progress"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /loans/{loanID}/adjustments [post]
+// @Security BearerAuth
+func (h *LoanHandler) ApplyScheduleAdjustment(w http.ResponseWriter, r *http.Request) {
+	loanID, err := h.loanIDFromURL(r)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	var req dto.ApplyAdjustmentRequest
+	if err := decodeJSON(r, &req); err != nil {
+		respondError(w, fmt.Errorf("%w: %w", apperrors.ErrInvalidArgument, err))
+		return
+	}
+	if err := req.Validate(); err != nil {
+		respondError(w, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err))
+		return
+	}
+
+	plan, err := h.service.ApplyScheduleAdjustment(r.Context(), loanID, req.Adjustment(), actorFromContext(r.Context()))
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, dto.NewAdjustmentPlanResponse(plan))
+}
+
+// RemoveScheduleAdjustment handles DELETE /loans/{loanID}/adjustments/{adjustmentID}.
+// @Summary Withdraw a payment holiday or zero-interest window
+// @Description Restores the installments an adjustment that has not started yet moved or recalculated. The adjustment stays in the loan's list, marked with who removed it.
+// @Tags Loans
+// @Produce json
+// @Param loanID path int true "Loan ID"
+// @Param adjustmentID path int true "Adjustment ID"
+// @Success 200 {object} dto.AdjustmentPlanResponse "Adjustment removed"
+// @Failure 400 {object} dto.ErrorResponse "Invalid loan or adjustment ID"
+// @Failure 403 {object} dto.ErrorResponse "Token lacks the admin scope"
+// @Failure 404 {object} dto.ErrorResponse "Loan not found, or no such active adjustment"
+// @Failure 409 {object} dto.ErrorResponse "Loan is paid off, the adjustment has started, or a payment is in progress"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /loans/{loanID}/adjustments/{adjustmentID} [delete]
+// @Security BearerAuth
+func (h *LoanHandler) RemoveScheduleAdjustment(w http.ResponseWriter, r *http.Request) {
+	loanID, err := h.loanIDFromURL(r)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	adjustmentID, err := int64URLParam(r, "adjustmentID")
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	plan, err := h.service.RemoveScheduleAdjustment(r.Context(), loanID, adjustmentID, actorFromContext(r.Context()))
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, dto.NewAdjustmentPlanResponse(plan))
+}
+
+// RebuildSchedule handles POST /admin/loans/{loanID}/schedule/rebuild.
+// @Summary Rebuild a loan schedule
+// @Description Regenerates the loan's unpaid installments from its terms: unpaid weeks whose due date, amount or status drifted are reset, missing weeks are added and unpaid weeks past the term are removed. Paid installments are never changed. With dry_run=true the changes are returned without being written.
+// @Tags Loans
+// @Produce json
+// @Param loanID path int true "Loan ID"
+// @Param dry_run query bool false "Return the changes without applying them"
+// @Success 200 {object} dto.ScheduleRebuildResponse "Schedule changes"
+// @Failure 400 {object} dto.ErrorResponse "Invalid loan ID or dry_run"
+// @Failure 403 {object} dto.ErrorResponse "Token lacks the admin scope"
+// @Failure 404 {object} dto.ErrorResponse "Loan not found"
+// @Failure 409 {object} dto.ErrorResponse "A payment on the loan is in progress, or a paid week lies beyond the loan's term"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /admin/loans/{loanID}/schedule/rebuild [post]
+// @Security BearerAuth
+func (h *LoanHandler) RebuildSchedule(w http.ResponseWriter, r *http.Request) {
+	loanID, err := h.loanIDFromURL(r)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	dryRun := false
+	if raw := r.URL.Query().Get("dry_run"); raw != "" {
+		if dryRun, err = strconv.ParseBool(raw); err != nil {
+			respondError(w, fmt.Errorf("%w: dry_run must be true or false", apperrors.ErrInvalidArgument))
+			return
+		}
+	}
+
+	rebuild, err := h.service.RebuildSchedule(r.Context(), loanID, dryRun)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	h.logger.InfoContext(r.Context(), "Loan schedule rebuild run", "loanID", loanID, "dryRun", dryRun, slog.String("by", actorFromContext(r.Context())))
+	respondJSON(w, http.StatusOK, dto.NewScheduleRebuildResponse(rebuild))
+}
@@ -2,21 +2,26 @@ package handler
 
 import (
 	"billing-engine/internal/api/handler/dto"
+	"billing-engine/internal/domain/customer"
 	"billing-engine/internal/domain/loan"
 	"billing-engine/internal/pkg/apperrors"
 	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 type MockLoanService struct {
@@ -31,27 +36,178 @@ func (m *MockLoanService) GetLoanSchedule(ctx context.Context, loanID int64) ([]
 	return nil, args.Error(1)
 }
 
-func (m *MockLoanService) GetLoan(ctx context.Context, loanID int64) (*loan.Loan, error) {
+func (m *MockLoanService) PlaceHold(ctx context.Context, loanID int64, reason, placedBy string) (*loan.Hold, error) {
+	args := m.Called(ctx, loanID, reason, placedBy)
+	if hold, ok := args.Get(0).(*loan.Hold); ok {
+		return hold, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockLoanService) ReleaseHold(ctx context.Context, loanID int64, releasedBy string) (*loan.Hold, error) {
+	args := m.Called(ctx, loanID, releasedBy)
+	if hold, ok := args.Get(0).(*loan.Hold); ok {
+		return hold, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockLoanService) RebuildSchedule(ctx context.Context, loanID int64, dryRun bool) (*loan.ScheduleRebuild, error) {
+	args := m.Called(ctx, loanID, dryRun)
+	if rebuild, ok := args.Get(0).(*loan.ScheduleRebuild); ok {
+		return rebuild, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockLoanService) RepriceLoan(ctx context.Context, loanID int64, rate float64, effectiveFrom time.Time, changedBy string) (*loan.Repricing, error) {
+	args := m.Called(ctx, loanID, rate, effectiveFrom, changedBy)
+	if repricing, ok := args.Get(0).(*loan.Repricing); ok {
+		return repricing, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockLoanService) RepriceLoans(ctx context.Context, filter loan.RepricingFilter, rate float64, effectiveFrom time.Time, changedBy string) (*loan.RepricingReport, error) {
+	args := m.Called(ctx, filter, rate, effectiveFrom, changedBy)
+	if report, ok := args.Get(0).(*loan.RepricingReport); ok {
+		return report, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockLoanService) ApplyScheduleAdjustment(ctx context.Context, loanID int64, adjustment loan.ScheduleAdjustment, appliedBy string) (*loan.AdjustmentPlan, error) {
+	args := m.Called(ctx, loanID, adjustment, appliedBy)
+	if plan, ok := args.Get(0).(*loan.AdjustmentPlan); ok {
+		return plan, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockLoanService) RemoveScheduleAdjustment(ctx context.Context, loanID, adjustmentID int64, removedBy string) (*loan.AdjustmentPlan, error) {
+	args := m.Called(ctx, loanID, adjustmentID, removedBy)
+	if plan, ok := args.Get(0).(*loan.AdjustmentPlan); ok {
+		return plan, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockLoanService) Prepay(ctx context.Context, loanID int64, amount loan.Money, option loan.PrepaymentOption, details loan.PaymentDetails, recordedBy string) (*loan.Reamortization, error) {
+	args := m.Called(ctx, loanID, amount, option, details, recordedBy)
+	if plan, ok := args.Get(0).(*loan.Reamortization); ok {
+		return plan, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockLoanService) PreviewRestructure(ctx context.Context, loanID int64, r loan.Restructure) (*loan.RestructurePreview, error) {
+	args := m.Called(ctx, loanID, r)
+	if preview, ok := args.Get(0).(*loan.RestructurePreview); ok {
+		return preview, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockLoanService) ChargeLateFees(ctx context.Context, loanID int64, policy loan.DelinquencyPolicy) ([]loan.Fee, error) {
+	args := m.Called(ctx, loanID, policy)
+	if fees, ok := args.Get(0).([]loan.Fee); ok {
+		return fees, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockLoanService) PostFee(ctx context.Context, loanID int64, feeType loan.FeeType, amount loan.Money, reason, postedBy string) (*loan.Fee, error) {
+	args := m.Called(ctx, loanID, feeType, amount, reason, postedBy)
+	if fee, ok := args.Get(0).(*loan.Fee); ok {
+		return fee, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockLoanService) ListPayments(ctx context.Context, loanID int64, limit int) ([]loan.Payment, error) {
+	args := m.Called(ctx, loanID, limit)
+	payments, _ := args.Get(0).([]loan.Payment)
+	return payments, args.Error(1)
+}
+
+func (m *MockLoanService) SearchLoans(ctx context.Context, q loan.LoanSearch) ([]loan.SearchMatch, error) {
+	args := m.Called(ctx, q)
+	matches, _ := args.Get(0).([]loan.SearchMatch)
+	return matches, args.Error(1)
+}
+
+func (m *MockLoanService) GetFees(ctx context.Context, loanID int64) ([]loan.Fee, error) {
 	args := m.Called(ctx, loanID)
+	fees, _ := args.Get(0).([]loan.Fee)
+	return fees, args.Error(1)
+}
+
+func (m *MockLoanService) RequestFeeWaiver(ctx context.Context, loanID, feeID int64, reason, requestedBy string) (*loan.FeeWaiver, error) {
+	args := m.Called(ctx, loanID, feeID, reason, requestedBy)
+	if waiver, ok := args.Get(0).(*loan.FeeWaiver); ok {
+		return waiver, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockLoanService) DecideFeeWaiver(ctx context.Context, loanID, feeID int64, approve bool, decidedBy string) (*loan.FeeWaiver, error) {
+	args := m.Called(ctx, loanID, feeID, approve, decidedBy)
+	if waiver, ok := args.Get(0).(*loan.FeeWaiver); ok {
+		return waiver, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockLoanService) GetLoan(ctx context.Context, loanID int64, includeSchedule bool) (*loan.Loan, error) {
+	args := m.Called(ctx, loanID, includeSchedule)
 	if loan, ok := args.Get(0).(*loan.Loan); ok {
 		return loan, args.Error(1)
 	}
 	return nil, args.Error(1)
 }
 
-func (m *MockLoanService) CreateLoan(ctx context.Context, customerID int64, annualInterestRate loan.Money, termWeeks int, amount loan.Money, time time.Time) (*loan.Loan, error) {
-	args := m.Called(ctx, customerID, annualInterestRate, termWeeks, amount)
+func (m *MockLoanService) CreateLoan(ctx context.Context, customerID int64, annualInterestRate loan.Money, termWeeks int, amount loan.Money, time time.Time, externalRef string, publicID uuid.UUID, product string) (*loan.Loan, error) {
+	args := m.Called(ctx, customerID, annualInterestRate, termWeeks, amount, externalRef, publicID, product)
 	if createdLoan, ok := args.Get(0).(*loan.Loan); ok {
 		return createdLoan, args.Error(1)
 	}
 	return nil, args.Error(1)
 }
 
-func (m *MockLoanService) MakePayment(ctx context.Context, loanID int64, amount loan.Money) error {
-	args := m.Called(ctx, loanID, amount)
+func (m *MockLoanService) GetLoanByExternalRef(ctx context.Context, externalRef string, includeSchedule bool) (*loan.Loan, error) {
+	args := m.Called(ctx, externalRef, includeSchedule)
+	if found, ok := args.Get(0).(*loan.Loan); ok {
+		return found, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockLoanService) ResolveLoanID(ctx context.Context, publicID uuid.UUID) (int64, error) {
+	args := m.Called(ctx, publicID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockLoanService) MakePayment(ctx context.Context, loanID int64, amount loan.Money, details loan.PaymentDetails) error {
+	args := m.Called(ctx, loanID, amount, details)
 	return args.Error(0)
 }
 
+func (m *MockLoanService) CollectionsByChannel(ctx context.Context, from, to time.Time) (*loan.CollectionsReport, error) {
+	args := m.Called(ctx, from, to)
+	if report, ok := args.Get(0).(*loan.CollectionsReport); ok {
+		return report, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockLoanService) TaxReport(ctx context.Context, from, to time.Time) (*loan.TaxReport, error) {
+	args := m.Called(ctx, from, to)
+	if report, ok := args.Get(0).(*loan.TaxReport); ok {
+		return report, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
 func (m *MockLoanService) GetOutstanding(ctx context.Context, loanID int64) (loan.Money, error) {
 	args := m.Called(ctx, loanID)
 	if outstanding, ok := args.Get(0).(loan.Money); ok {
@@ -60,6 +216,12 @@ func (m *MockLoanService) GetOutstanding(ctx context.Context, loanID int64) (loa
 	return 0, args.Error(1)
 }
 
+func (m *MockLoanService) GetOutstandingBreakdown(ctx context.Context, loanID int64) (*loan.OutstandingBreakdown, error) {
+	args := m.Called(ctx, loanID)
+	breakdown, _ := args.Get(0).(*loan.OutstandingBreakdown)
+	return breakdown, args.Error(1)
+}
+
 func (m *MockLoanService) IsDelinquent(ctx context.Context, loanID int64) (bool, error) {
 	args := m.Called(ctx, loanID)
 	if isDelinquent, ok := args.Get(0).(bool); ok {
@@ -68,6 +230,25 @@ func (m *MockLoanService) IsDelinquent(ctx context.Context, loanID int64) (bool,
 	return false, args.Error(1)
 }
 
+func (m *MockLoanService) GetLastModified(ctx context.Context, loanID int64) (time.Time, error) {
+	args := m.Called(ctx, loanID)
+	if lastModified, ok := args.Get(0).(time.Time); ok {
+		return lastModified, args.Error(1)
+	}
+	return time.Time{}, args.Error(1)
+}
+
+func (m *MockLoanService) StreamLoans(ctx context.Context, filter loan.LoanFilter, fn func(*loan.Loan) error) error {
+	args := m.Called(ctx, filter)
+	loans, _ := args.Get(0).([]*loan.Loan)
+	for _, l := range loans {
+		if err := fn(l); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
 func TestLoanHandlerGetLoan(t *testing.T) {
 	mockService := new(MockLoanService)
 	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
@@ -79,7 +260,7 @@ func TestLoanHandlerGetLoan(t *testing.T) {
 			ID: loanID,
 		}
 
-		mockService.On("GetLoan", mock.Anything, loanID).Return(mockLoan, nil)
+		mockService.On("GetLoan", mock.Anything, loanID, false).Return(mockLoan, nil)
 
 		req := httptest.NewRequest(http.MethodGet, "/loans/123", nil)
 		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, &chi.Context{
@@ -97,6 +278,37 @@ func TestLoanHandlerGetLoan(t *testing.T) {
 		mockService.AssertExpectations(t)
 	})
 
+	t.Run("returns 304 while the loan is unchanged", func(t *testing.T) {
+		mockLoan := &loan.Loan{ID: 321, Schedule: []loan.ScheduleEntry{{WeekNumber: 1, DueAmount: 100, Status: loan.PaymentStatusPending}}}
+		mockService.On("GetLoan", mock.Anything, int64(321), true).Return(mockLoan, nil)
+		get := func(etag string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodGet, "/loans/321?include=schedule", nil)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, &chi.Context{
+				URLParams: chi.RouteParams{Keys: []string{"loanID"}, Values: []string{"321"}},
+			}))
+			if etag != "" {
+				req.Header.Set("If-None-Match", etag)
+			}
+			rec := httptest.NewRecorder()
+			handler.GetLoan(rec, req)
+			return rec
+		}
+
+		first := get("")
+		assert.Equal(t, http.StatusOK, first.Code)
+		etag := first.Header().Get("ETag")
+		assert.NotEmpty(t, etag)
+
+		cached := get(etag)
+		assert.Equal(t, http.StatusNotModified, cached.Code)
+		assert.Empty(t, cached.Body.Bytes())
+
+		mockLoan.Schedule[0].Status = loan.PaymentStatusPaid
+		changed := get(etag)
+		assert.Equal(t, http.StatusOK, changed.Code)
+		assert.NotEqual(t, etag, changed.Header().Get("ETag"))
+	})
+
 	t.Run("returns error for invalid loan ID", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/loans/invalid", nil)
 		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, &chi.Context{
@@ -115,7 +327,7 @@ func TestLoanHandlerGetLoan(t *testing.T) {
 
 	t.Run("returns error when loan not found", func(t *testing.T) {
 		loanID := int64(2)
-		mockService.On("GetLoan", mock.Anything, loanID).Return((*loan.Loan)(nil), apperrors.ErrNotFound)
+		mockService.On("GetLoan", mock.Anything, loanID, false).Return((*loan.Loan)(nil), apperrors.ErrNotFound)
 
 		req := httptest.NewRequest(http.MethodGet, "/loans/2", nil)
 		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, &chi.Context{
@@ -135,7 +347,7 @@ func TestLoanHandlerGetLoan(t *testing.T) {
 
 	t.Run("returns internal server error for unexpected errors", func(t *testing.T) {
 		loanID := int64(3)
-		mockService.On("GetLoan", mock.Anything, loanID).Return((*loan.Loan)(nil), errors.New("unexpected error"))
+		mockService.On("GetLoan", mock.Anything, loanID, false).Return((*loan.Loan)(nil), errors.New("unexpected error"))
 
 		req := httptest.NewRequest(http.MethodGet, "/loans/3", nil)
 		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, &chi.Context{
@@ -152,4 +364,984 @@ func TestLoanHandlerGetLoan(t *testing.T) {
 		assert.Equal(t, "An unexpected error occurred.", resp.Error.Message)
 		mockService.AssertExpectations(t)
 	})
+
+	t.Run("answers in the negotiated locale", func(t *testing.T) {
+		loanID := int64(4)
+		mockService.On("GetLoan", mock.Anything, loanID, false).Return((*loan.Loan)(nil), apperrors.ErrNotFound)
+
+		req := httptest.NewRequest(http.MethodGet, "/loans/4", nil)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, &chi.Context{
+			URLParams: chi.RouteParams{Keys: []string{"loanID"}, Values: []string{"4"}},
+		}))
+		rec := httptest.NewRecorder()
+		rec.Header().Set("Content-Language", "id")
+
+		handler.GetLoan(rec, req)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		var resp dto.ErrorResponse
+		assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		assert.Equal(t, "Data tidak ditemukan.", resp.Error.Message)
+	})
+}
+
+func TestLoanHandlerFindLoanByExternalRef(t *testing.T) {
+	mockService := new(MockLoanService)
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	handler := NewLoanHandler(mockService, logger)
+	externalRef := "LOS-42"
+
+	t.Run("successfully retrieves loan by external reference", func(t *testing.T) {
+		mockService.On("GetLoanByExternalRef", mock.Anything, externalRef, false).
+			Return(&loan.Loan{ID: 42, ExternalRef: &externalRef}, nil).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/loans?external_ref="+externalRef, nil)
+		rec := httptest.NewRecorder()
+
+		handler.FindLoanByExternalRef(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var resp dto.LoanResponse
+		assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		assert.Equal(t, "42", resp.ID)
+		assert.Equal(t, externalRef, *resp.ExternalRef)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("returns bad request without external reference", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/loans", nil)
+		rec := httptest.NewRecorder()
+
+		handler.FindLoanByExternalRef(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("returns not found for unknown reference", func(t *testing.T) {
+		mockService.On("GetLoanByExternalRef", mock.Anything, "missing", false).
+			Return((*loan.Loan)(nil), apperrors.ErrNotFound).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/loans?external_ref=missing", nil)
+		rec := httptest.NewRecorder()
+
+		handler.FindLoanByExternalRef(rec, req)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestLoanHandlerSearchLoans(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+
+	t.Run("passes the criteria with an inclusive window", func(t *testing.T) {
+		mockService := new(MockLoanService)
+		handler := NewLoanHandler(mockService, logger)
+		amount := 110.5
+		want := loan.LoanSearch{
+			Amount:       &amount,
+			From:         time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+			To:           time.Date(2025, 3, 8, 0, 0, 0, 0, time.UTC),
+			CustomerName: "doe",
+			Limit:        5,
+		}
+		mockService.On("SearchLoans", mock.Anything, want).
+			Return([]loan.SearchMatch{{LoanID: 3, Status: loan.StatusActive, Payment: &loan.PaymentMatch{ID: 9, Amount: 110}}}, nil).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/loans/search?amount=110.5&from=2025-03-01&to=2025-03-07&customer_name=doe&limit=5", nil)
+		rec := httptest.NewRecorder()
+
+		handler.SearchLoans(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var resp []dto.LoanSearchMatchResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		require.Len(t, resp, 1)
+		assert.Equal(t, "3", resp[0].LoanID)
+		assert.Equal(t, "9", resp[0].Payment.ID)
+		mockService.AssertExpectations(t)
+	})
+
+	for name, query := range map[string]string{
+		"a malformed amount": "amount=lots&from=2025-03-01&to=2025-03-07",
+		"a malformed date":   "reference=TRF-1&from=03/01/2025&to=2025-03-07",
+		"a zero limit":       "reference=TRF-1&limit=0",
+	} {
+		t.Run("rejects "+name, func(t *testing.T) {
+			mockService := new(MockLoanService)
+			handler := NewLoanHandler(mockService, logger)
+
+			req := httptest.NewRequest(http.MethodGet, "/loans/search?"+query, nil)
+			rec := httptest.NewRecorder()
+
+			handler.SearchLoans(rec, req)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			mockService.AssertNotCalled(t, "SearchLoans", mock.Anything, mock.Anything)
+		})
+	}
+
+	t.Run("maps invalid searches to bad request", func(t *testing.T) {
+		mockService := new(MockLoanService)
+		handler := NewLoanHandler(mockService, logger)
+		mockService.On("SearchLoans", mock.Anything, loan.LoanSearch{}).
+			Return(nil, fmt.Errorf("%w: search needs a reference, an amount or a customer name", apperrors.ErrInvalidArgument)).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/loans/search", nil)
+		rec := httptest.NewRecorder()
+
+		handler.SearchLoans(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestLoanHandlerStreamLoans(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+
+	t.Run("streams loans after the cursor as NDJSON", func(t *testing.T) {
+		mockService := new(MockLoanService)
+		mockService.On("StreamLoans", mock.Anything, loan.LoanFilter{AfterID: 40}).
+			Return([]*loan.Loan{{ID: 41, Status: loan.StatusActive}, {ID: 42, Status: loan.StatusPaidOff}}, nil).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/loans?cursor=40", nil)
+		req.Header.Set("Accept", "application/x-ndjson")
+		rec := httptest.NewRecorder()
+
+		NewLoanHandler(mockService, logger).FindLoanByExternalRef(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, ndjsonContentType, rec.Header().Get("Content-Type"))
+		lines := strings.Split(strings.TrimSuffix(rec.Body.String(), "\n"), "\n")
+		require.Len(t, lines, 2)
+		var last dto.LoanResponse
+		require.NoError(t, json.Unmarshal([]byte(lines[1]), &last))
+		assert.Equal(t, "42", last.ID)
+		assert.Equal(t, string(loan.StatusPaidOff), last.Status)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("answers an empty book with an empty stream", func(t *testing.T) {
+		mockService := new(MockLoanService)
+		mockService.On("StreamLoans", mock.Anything, loan.LoanFilter{}).Return(nil, nil).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/loans", nil)
+		req.Header.Set("Accept", "application/json, application/x-ndjson;q=0.9")
+		rec := httptest.NewRecorder()
+
+		NewLoanHandler(mockService, logger).FindLoanByExternalRef(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, ndjsonContentType, rec.Header().Get("Content-Type"))
+		assert.Empty(t, rec.Body.String())
+	})
+
+	t.Run("filters by days past due", func(t *testing.T) {
+		mockService := new(MockLoanService)
+		mockService.On("StreamLoans", mock.Anything, loan.LoanFilter{AfterID: 7, MinDaysPastDue: 30}).
+			Return([]*loan.Loan{{ID: 9, Status: loan.StatusActive, DaysPastDue: 45}}, nil).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/loans?cursor=7&dpd_gte=30", nil)
+		req.Header.Set("Accept", "application/x-ndjson")
+		rec := httptest.NewRecorder()
+
+		NewLoanHandler(mockService, logger).FindLoanByExternalRef(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"daysPastDue":45`)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("filters by status", func(t *testing.T) {
+		mockService := new(MockLoanService)
+		mockService.On("StreamLoans", mock.Anything, loan.LoanFilter{Status: loan.StatusDelinquent}).
+			Return([]*loan.Loan{{ID: 3, Status: loan.StatusDelinquent}}, nil).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/loans?status=delinquent", nil)
+		req.Header.Set("Accept", "application/x-ndjson")
+		rec := httptest.NewRecorder()
+
+		NewLoanHandler(mockService, logger).FindLoanByExternalRef(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("rejects an unknown status", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/loans?status=closed", nil)
+		req.Header.Set("Accept", "application/x-ndjson")
+		rec := httptest.NewRecorder()
+
+		NewLoanHandler(new(MockLoanService), logger).FindLoanByExternalRef(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("rejects an invalid days past due filter", func(t *testing.T) {
+		for _, value := range []string{"-1", "thirty"} {
+			req := httptest.NewRequest(http.MethodGet, "/loans?dpd_gte="+value, nil)
+			req.Header.Set("Accept", "application/x-ndjson")
+			rec := httptest.NewRecorder()
+
+			NewLoanHandler(new(MockLoanService), logger).FindLoanByExternalRef(rec, req)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code, value)
+		}
+	})
+
+	t.Run("rejects an invalid cursor", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/loans?cursor=abc", nil)
+		req.Header.Set("Accept", "application/x-ndjson")
+		rec := httptest.NewRecorder()
+
+		NewLoanHandler(new(MockLoanService), logger).FindLoanByExternalRef(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("reports errors before the first line as JSON", func(t *testing.T) {
+		mockService := new(MockLoanService)
+		mockService.On("StreamLoans", mock.Anything, loan.LoanFilter{}).Return(nil, apperrors.ErrForbidden).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/loans", nil)
+		req.Header.Set("Accept", "application/x-ndjson")
+		rec := httptest.NewRecorder()
+
+		NewLoanHandler(mockService, logger).FindLoanByExternalRef(rec, req)
+
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	})
+
+	t.Run("aborts the response when the stream fails midway", func(t *testing.T) {
+		mockService := new(MockLoanService)
+		mockService.On("StreamLoans", mock.Anything, loan.LoanFilter{}).
+			Return([]*loan.Loan{{ID: 1}}, apperrors.ErrDatabase).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/loans", nil)
+		req.Header.Set("Accept", "application/x-ndjson")
+		rec := httptest.NewRecorder()
+
+		assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+			NewLoanHandler(mockService, logger).FindLoanByExternalRef(rec, req)
+		})
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"id":"1"`)
+	})
+}
+
+func TestLoanHandlerGetLoanByPublicID(t *testing.T) {
+	mockService := new(MockLoanService)
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	handler := NewLoanHandler(mockService, logger)
+	publicID := uuid.New()
+
+	mockService.On("ResolveLoanID", mock.Anything, publicID).Return(int64(55), nil).Once()
+	mockService.On("GetLastModified", mock.Anything, int64(55)).Return(time.Now(), nil).Once()
+	mockService.On("GetOutstandingBreakdown", mock.Anything, int64(55)).Return(&loan.OutstandingBreakdown{LoanID: 55, Total: 250}, nil).Once()
+
+	req := httptest.NewRequest(http.MethodGet, "/loans/"+publicID.String()+"/outstanding", nil)
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, &chi.Context{
+		URLParams: chi.RouteParams{Keys: []string{"loanID"}, Values: []string{publicID.String()}},
+	}))
+	rec := httptest.NewRecorder()
+
+	handler.GetOutstanding(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var resp dto.OutstandingResponse
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.Equal(t, "55", resp.LoanID)
+	mockService.AssertExpectations(t)
+}
+
+func TestLoanHandlerMakePaymentRejectsUnsafeBodies(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	handler := NewLoanHandler(new(MockLoanService), logger)
+	withLoanID := func(req *http.Request) *http.Request {
+		return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, &chi.Context{
+			URLParams: chi.RouteParams{Keys: []string{"loanID"}, Values: []string{"7"}},
+		}))
+	}
+
+	t.Run("returns 413 when the body exceeds the limit", func(t *testing.T) {
+		req := withLoanID(httptest.NewRequest(http.MethodPost, "/loans/7/payments", strings.NewReader(`{"amount":"1000000"}`)))
+		rec := httptest.NewRecorder()
+		req.Body = http.MaxBytesReader(rec, req.Body, 8)
+
+		handler.MakePayment(rec, req)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+		var resp dto.ErrorResponse
+		assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		assert.Equal(t, "Request body exceeds the 8 byte limit.", resp.Error.Message)
+	})
+
+	t.Run("returns 400 for deeply nested JSON", func(t *testing.T) {
+		body := `{"amount":` + strings.Repeat("[", maxJSONDepth) + strings.Repeat("]", maxJSONDepth) + "}"
+		req := withLoanID(httptest.NewRequest(http.MethodPost, "/loans/7/payments", strings.NewReader(body)))
+		rec := httptest.NewRecorder()
+
+		handler.MakePayment(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "JSON nesting exceeds")
+	})
+}
+
+func TestLoanHandlerMakePaymentPassesChannelDetails(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	withLoanID := func(req *http.Request) *http.Request {
+		return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, &chi.Context{
+			URLParams: chi.RouteParams{Keys: []string{"loanID"}, Values: []string{"7"}},
+		}))
+	}
+	body := `{"amount":"110","channel":"CASH","reference":"RCPT-1","collectorId":"agent-7"}`
+	details := loan.PaymentDetails{Channel: loan.ChannelCash, Reference: "RCPT-1", CollectorID: "agent-7"}
+
+	t.Run("records the payment", func(t *testing.T) {
+		mockService := new(MockLoanService)
+		mockService.On("MakePayment", mock.Anything, int64(7), loan.Money(110), details).Return(nil).Once()
+		rec := httptest.NewRecorder()
+
+		NewLoanHandler(mockService, logger).MakePayment(rec, withLoanID(httptest.NewRequest(http.MethodPost, "/loans/7/payments", strings.NewReader(body))))
+
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("returns 409 for a reference already posted", func(t *testing.T) {
+		mockService := new(MockLoanService)
+		mockService.On("MakePayment", mock.Anything, int64(7), loan.Money(110), details).
+			Return(fmt.Errorf("%w: a CASH payment with reference \"RCPT-1\" was already posted", apperrors.ErrAlreadyExists)).Once()
+		rec := httptest.NewRecorder()
+
+		NewLoanHandler(mockService, logger).MakePayment(rec, withLoanID(httptest.NewRequest(http.MethodPost, "/loans/7/payments", strings.NewReader(body))))
+
+		assert.Equal(t, http.StatusConflict, rec.Code, rec.Body.String())
+	})
+}
+
+func TestLoanHandlerMakePaymentReportsExpectedAmount(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	mockService := new(MockLoanService)
+	handler := NewLoanHandler(mockService, logger)
+	mockService.On("MakePayment", mock.Anything, int64(7), loan.Money(100), loan.PaymentDetails{}).
+		Return(fmt.Errorf("payment failed: %w", &apperrors.PaymentAmountError{Amount: 100, Expected: 110, Digits: 2}))
+
+	req := httptest.NewRequest(http.MethodPost, "/loans/7/payments", strings.NewReader(`{"amount":"100"}`))
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, &chi.Context{
+		URLParams: chi.RouteParams{Keys: []string{"loanID"}, Values: []string{"7"}},
+	}))
+	rec := httptest.NewRecorder()
+
+	handler.MakePayment(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	var resp dto.ErrorResponse
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.Equal(t, "110.00", resp.Error.ExpectedAmount)
+	assert.Contains(t, resp.Error.Message, "does not match due amount 110.00")
+	mockService.AssertExpectations(t)
+}
+
+func TestLoanHandlerCreateLoanConflicts(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	body := `{"customerId":1,"principal":1000,"termWeeks":4,"annualInterestRate":0.1,"startDate":"2025-01-06"}`
+
+	for name, err := range map[string]error{
+		"customer already has a loan": apperrors.Wrap(customer.ErrCustomerAlreadyHasLoan, apperrors.CodeConflict, "customer already has a loan").WithMeta("loanId", "3"),
+		"customer changed meanwhile":  fmt.Errorf("%w: customer 1 is not found, inactive or already has an active loan", apperrors.ErrConflict),
+	} {
+		t.Run(name, func(t *testing.T) {
+			mockService := new(MockLoanService)
+			mockService.On("CreateLoan", mock.Anything, int64(1), mock.Anything, 4, mock.Anything, "", uuid.Nil, "").Return(nil, err).Once()
+			rec := httptest.NewRecorder()
+
+			NewLoanHandler(mockService, logger).CreateLoan(rec, httptest.NewRequest(http.MethodPost, "/loans", strings.NewReader(body)))
+
+			assert.Equal(t, http.StatusConflict, rec.Code, rec.Body.String())
+			var resp dto.ErrorResponse
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+			assert.Equal(t, "CONFLICT", resp.Error.Code)
+			assert.Equal(t, apperrors.Metadata(err), resp.Error.Details)
+		})
+	}
+}
+
+func TestRespondError(t *testing.T) {
+	t.Run("hides internal errors", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		rec.Header().Set("Content-Language", "id")
+
+		respondError(rec, apperrors.WrapDatabaseError(errors.New(`relation "loans" does not exist`), "failed to load loan"))
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.JSONEq(t, `{"error":{"code":"DB_ERROR","message":"Terjadi kesalahan yang tidak terduga."}}`, rec.Body.String())
+	})
+
+	t.Run("marks retryable errors", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+
+		respondError(rec, fmt.Errorf("%w: attachment storage is not configured", apperrors.ErrUnavailable))
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.JSONEq(t, `{"error":{"code":"UNAVAILABLE","message":"service unavailable: attachment storage is not configured","retryable":true}}`, rec.Body.String())
+	})
+
+	t.Run("maps domain errors with a code", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+
+		respondError(rec, customer.ErrNotFound)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.JSONEq(t, `{"error":{"code":"NOT_FOUND","message":"Resource not found."}}`, rec.Body.String())
+	})
+}
+
+func TestLoanHandlerHolds(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	withLoanID := func(req *http.Request) *http.Request {
+		return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, &chi.Context{
+			URLParams: chi.RouteParams{Keys: []string{"loanID"}, Values: []string{"7"}},
+		}))
+	}
+	placedAt := time.Date(2025, 4, 2, 9, 0, 0, 0, time.UTC)
+
+	t.Run("places a hold", func(t *testing.T) {
+		mockService := new(MockLoanService)
+		mockService.On("PlaceHold", mock.Anything, int64(7), "disputed", "").
+			Return(&loan.Hold{ID: 3, LoanID: 7, Reason: "disputed", PlacedAt: placedAt}, nil).Once()
+		rec := httptest.NewRecorder()
+
+		NewLoanHandler(mockService, logger).PlaceHold(rec, withLoanID(httptest.NewRequest(http.MethodPost, "/loans/7/hold", strings.NewReader(`{"reason":"disputed"}`))))
+
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		var resp dto.LoanHoldResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		assert.Equal(t, "disputed", resp.Reason)
+		assert.Nil(t, resp.ReleasedAt)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("requires a reason", func(t *testing.T) {
+		mockService := new(MockLoanService)
+		rec := httptest.NewRecorder()
+
+		NewLoanHandler(mockService, logger).PlaceHold(rec, withLoanID(httptest.NewRequest(http.MethodPost, "/loans/7/hold", strings.NewReader(`{}`))))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		mockService.AssertNotCalled(t, "PlaceHold", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("returns 409 for a loan already on hold", func(t *testing.T) {
+		mockService := new(MockLoanService)
+		mockService.On("PlaceHold", mock.Anything, int64(7), "disputed", "").Return(nil, apperrors.ErrConflict).Once()
+		rec := httptest.NewRecorder()
+
+		NewLoanHandler(mockService, logger).PlaceHold(rec, withLoanID(httptest.NewRequest(http.MethodPost, "/loans/7/hold", strings.NewReader(`{"reason":"disputed"}`))))
+
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+
+	t.Run("releases the hold", func(t *testing.T) {
+		mockService := new(MockLoanService)
+		releasedAt := placedAt.Add(time.Hour)
+		mockService.On("ReleaseHold", mock.Anything, int64(7), "").
+			Return(&loan.Hold{ID: 3, LoanID: 7, Reason: "disputed", PlacedAt: placedAt, ReleasedAt: &releasedAt}, nil).Once()
+		rec := httptest.NewRecorder()
+
+		NewLoanHandler(mockService, logger).ReleaseHold(rec, withLoanID(httptest.NewRequest(http.MethodDelete, "/loans/7/hold", nil)))
+
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var resp dto.LoanHoldResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		require.NotNil(t, resp.ReleasedAt)
+		assert.True(t, releasedAt.Equal(*resp.ReleasedAt))
+	})
+
+	t.Run("returns 404 when the loan is not on hold", func(t *testing.T) {
+		mockService := new(MockLoanService)
+		mockService.On("ReleaseHold", mock.Anything, int64(7), "").Return(nil, apperrors.ErrNotFound).Once()
+		rec := httptest.NewRecorder()
+
+		NewLoanHandler(mockService, logger).ReleaseHold(rec, withLoanID(httptest.NewRequest(http.MethodDelete, "/loans/7/hold", nil)))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("reports a payment on a held loan as a conflict", func(t *testing.T) {
+		mockService := new(MockLoanService)
+		mockService.On("MakePayment", mock.Anything, int64(7), loan.Money(110), loan.PaymentDetails{}).
+			Return(fmt.Errorf("%w: loan 7 is on hold: disputed", apperrors.ErrLoanOnHold)).Once()
+		rec := httptest.NewRecorder()
+
+		NewLoanHandler(mockService, logger).MakePayment(rec, withLoanID(httptest.NewRequest(http.MethodPost, "/loans/7/payments", strings.NewReader(`{"amount":"110"}`))))
+
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+}
+
+func TestCheckJSONDepth(t *testing.T) {
+	assert.NoError(t, checkJSONDepth([]byte(`{"a":[{"b":1}]}`), 3))
+	assert.Error(t, checkJSONDepth([]byte(`{"a":[{"b":[]}]}`), 3))
+	assert.NoError(t, checkJSONDepth([]byte(`{"note":"[[[[{{{{ \"[[[["}`), 1), "brackets inside strings do not count")
+}
+
+func TestLoanHandlerRebuildSchedule(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	withLoanID := func(req *http.Request) *http.Request {
+		return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, &chi.Context{
+			URLParams: chi.RouteParams{Keys: []string{"loanID"}, Values: []string{"7"}},
+		}))
+	}
+	dueDate := time.Date(2025, 1, 27, 0, 0, 0, 0, time.UTC)
+
+	t.Run("returns the diff of a dry run", func(t *testing.T) {
+		mockService := new(MockLoanService)
+		mockService.On("RebuildSchedule", mock.Anything, int64(7), true).Return(&loan.ScheduleRebuild{
+			LoanID: 7, DryRun: true, PaidKept: 1, Status: loan.StatusActive,
+			Changes: []loan.ScheduleChange{{
+				Kind: loan.ScheduleEntryUpdated, WeekNumber: 3,
+				Before: &loan.ScheduleEntry{ID: 3, WeekNumber: 3, DueDate: dueDate, DueAmount: 99, Status: loan.PaymentStatusMissed},
+				After:  &loan.ScheduleEntry{ID: 3, WeekNumber: 3, DueDate: dueDate, DueAmount: 110, Status: loan.PaymentStatusPending},
+			}},
+		}, nil).Once()
+		rec := httptest.NewRecorder()
+
+		NewLoanHandler(mockService, logger).RebuildSchedule(rec, withLoanID(httptest.NewRequest(http.MethodPost, "/admin/loans/7/schedule/rebuild?dry_run=true", nil)))
+
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var resp dto.ScheduleRebuildResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		assert.True(t, resp.DryRun)
+		assert.Equal(t, 1, resp.PaidKept)
+		require.Len(t, resp.Changes, 1)
+		assert.Equal(t, "UPDATED", resp.Changes[0].Kind)
+		assert.Equal(t, "99.00", resp.Changes[0].Before.DueAmount)
+		assert.Equal(t, "110.00", resp.Changes[0].After.DueAmount)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("applies the rebuild without dry_run", func(t *testing.T) {
+		mockService := new(MockLoanService)
+		mockService.On("RebuildSchedule", mock.Anything, int64(7), false).
+			Return(&loan.ScheduleRebuild{LoanID: 7, Status: loan.StatusActive, Changes: []loan.ScheduleChange{}}, nil).Once()
+		rec := httptest.NewRecorder()
+
+		NewLoanHandler(mockService, logger).RebuildSchedule(rec, withLoanID(httptest.NewRequest(http.MethodPost, "/admin/loans/7/schedule/rebuild", nil)))
+
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.Contains(t, rec.Body.String(), `"changes":[]`)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("rejects an unreadable dry_run", func(t *testing.T) {
+		mockService := new(MockLoanService)
+		rec := httptest.NewRecorder()
+
+		NewLoanHandler(mockService, logger).RebuildSchedule(rec, withLoanID(httptest.NewRequest(http.MethodPost, "/admin/loans/7/schedule/rebuild?dry_run=maybe", nil)))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		mockService.AssertNotCalled(t, "RebuildSchedule", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("returns 409 when a paid week cannot be reconciled", func(t *testing.T) {
+		mockService := new(MockLoanService)
+		mockService.On("RebuildSchedule", mock.Anything, int64(7), false).Return(nil, apperrors.ErrConflict).Once()
+		rec := httptest.NewRecorder()
+
+		NewLoanHandler(mockService, logger).RebuildSchedule(rec, withLoanID(httptest.NewRequest(http.MethodPost, "/admin/loans/7/schedule/rebuild", nil)))
+
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+}
+
+func TestLoanHandlerRepriceLoan(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	withLoanID := func(req *http.Request) *http.Request {
+		return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, &chi.Context{
+			URLParams: chi.RouteParams{Keys: []string{"loanID"}, Values: []string{"7"}},
+		}))
+	}
+	effectiveFrom := time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC)
+
+	t.Run("changes the rate", func(t *testing.T) {
+		mockService := new(MockLoanService)
+		mockService.On("RepriceLoan", mock.Anything, int64(7), 0.16, effectiveFrom, "").Return(&loan.Repricing{
+			Change:              loan.RateChange{ID: 2, LoanID: 7, PreviousRate: 0.1, Rate: 0.16, EffectiveFrom: effectiveFrom},
+			WeeklyPaymentAmount: 116,
+			TotalLoanAmount:     342,
+			Changes: []loan.ScheduleChange{{
+				Kind: loan.ScheduleEntryUpdated, WeekNumber: 2,
+				Before: &loan.ScheduleEntry{ID: 2, WeekNumber: 2, DueDate: effectiveFrom, DueAmount: 110, Status: loan.PaymentStatusPending},
+				After:  &loan.ScheduleEntry{ID: 2, WeekNumber: 2, DueDate: effectiveFrom, DueAmount: 116, Status: loan.PaymentStatusPending},
+			}},
+		}, nil).Once()
+		rec := httptest.NewRecorder()
+
+		NewLoanHandler(mockService, logger).RepriceLoan(rec, withLoanID(httptest.NewRequest(http.MethodPost, "/loans/7/rate",
+			strings.NewReader(`{"annualInterestRate":0.16,"effectiveFrom":"2025-01-20"}`))))
+
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		var resp dto.RepricingResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		assert.Equal(t, "0.16", resp.RateChange.InterestRate)
+		assert.Equal(t, "2025-01-20", resp.RateChange.EffectiveFrom)
+		assert.Equal(t, "342.00", resp.TotalLoanAmount)
+		require.Len(t, resp.Changes, 1)
+		assert.Equal(t, "116.00", resp.Changes[0].After.DueAmount)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("requires an effective date", func(t *testing.T) {
+		mockService := new(MockLoanService)
+		rec := httptest.NewRecorder()
+
+		NewLoanHandler(mockService, logger).RepriceLoan(rec, withLoanID(httptest.NewRequest(http.MethodPost, "/loans/7/rate", strings.NewReader(`{"annualInterestRate":0.16}`))))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		mockService.AssertNotCalled(t, "RepriceLoan", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("returns 409 when the loan is paid past the date", func(t *testing.T) {
+		mockService := new(MockLoanService)
+		mockService.On("RepriceLoan", mock.Anything, int64(7), 0.16, effectiveFrom, "").Return(nil, apperrors.ErrConflict).Once()
+		rec := httptest.NewRecorder()
+
+		NewLoanHandler(mockService, logger).RepriceLoan(rec, withLoanID(httptest.NewRequest(http.MethodPost, "/loans/7/rate",
+			strings.NewReader(`{"annualInterestRate":0.16,"effectiveFrom":"2025-01-20"}`))))
+
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+}
+
+func TestLoanHandlerScheduleAdjustments(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	withParams := func(req *http.Request, keys, values []string) *http.Request {
+		return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, &chi.Context{
+			URLParams: chi.RouteParams{Keys: keys, Values: values},
+		}))
+	}
+	startsOn := time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC)
+	holiday := loan.ScheduleAdjustment{Kind: loan.AdjustmentPaymentHoliday, StartsOn: startsOn, Weeks: 2, Reason: "medical leave"}
+	plan := &loan.AdjustmentPlan{
+		Adjustment: loan.ScheduleAdjustment{ID: 4, LoanID: 7, Kind: loan.AdjustmentPaymentHoliday, StartsOn: startsOn, Weeks: 2, Reason: "medical leave"},
+		Changes: []loan.ScheduleChange{{
+			Kind: loan.ScheduleEntryUpdated, WeekNumber: 2,
+			Before: &loan.ScheduleEntry{ID: 2, WeekNumber: 2, DueDate: startsOn, DueAmount: 110, Status: loan.PaymentStatusPending},
+			After:  &loan.ScheduleEntry{ID: 2, WeekNumber: 2, DueDate: startsOn.AddDate(0, 0, 14), DueAmount: 110, Status: loan.PaymentStatusPending},
+		}},
+		TotalLoanAmount: 330,
+	}
+
+	t.Run("applies an adjustment", func(t *testing.T) {
+		mockService := new(MockLoanService)
+		mockService.On("ApplyScheduleAdjustment", mock.Anything, int64(7), holiday, "").Return(plan, nil).Once()
+		rec := httptest.NewRecorder()
+
+		NewLoanHandler(mockService, logger).ApplyScheduleAdjustment(rec, withParams(httptest.NewRequest(http.MethodPost, "/loans/7/adjustments",
+			strings.NewReader(`{"kind":"PAYMENT_HOLIDAY","startsOn":"2025-01-20","weeks":2,"reason":"medical leave"}`)), []string{"loanID"}, []string{"7"}))
+
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		var resp dto.AdjustmentPlanResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		assert.Equal(t, "4", resp.Adjustment.ID)
+		assert.Equal(t, "330.00", resp.TotalLoanAmount)
+		require.Len(t, resp.Changes, 1)
+		assert.Equal(t, "2025-02-03", resp.Changes[0].After.DueDate)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("rejects an unknown kind", func(t *testing.T) {
+		mockService := new(MockLoanService)
+		rec := httptest.NewRecorder()
+
+		NewLoanHandler(mockService, logger).ApplyScheduleAdjustment(rec, withParams(httptest.NewRequest(http.MethodPost, "/loans/7/adjustments",
+			strings.NewReader(`{"kind":"DISCOUNT","startsOn":"2025-01-20","weeks":2,"reason":"r"}`)), []string{"loanID"}, []string{"7"}))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		mockService.AssertNotCalled(t, "ApplyScheduleAdjustment", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("returns 409 for a delinquent loan", func(t *testing.T) {
+		mockService := new(MockLoanService)
+		mockService.On("ApplyScheduleAdjustment", mock.Anything, int64(7), holiday, "").Return(nil, apperrors.ErrConflict).Once()
+		rec := httptest.NewRecorder()
+
+		NewLoanHandler(mockService, logger).ApplyScheduleAdjustment(rec, withParams(httptest.NewRequest(http.MethodPost, "/loans/7/adjustments",
+			strings.NewReader(`{"kind":"PAYMENT_HOLIDAY","startsOn":"2025-01-20","weeks":2,"reason":"medical leave"}`)), []string{"loanID"}, []string{"7"}))
+
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+
+	t.Run("removes an adjustment", func(t *testing.T) {
+		mockService := new(MockLoanService)
+		mockService.On("RemoveScheduleAdjustment", mock.Anything, int64(7), int64(4), "").Return(plan, nil).Once()
+		rec := httptest.NewRecorder()
+
+		NewLoanHandler(mockService, logger).RemoveScheduleAdjustment(rec, withParams(httptest.NewRequest(http.MethodDelete, "/loans/7/adjustments/4", nil),
+			[]string{"loanID", "adjustmentID"}, []string{"7", "4"}))
+
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("rejects an invalid adjustment ID", func(t *testing.T) {
+		mockService := new(MockLoanService)
+		rec := httptest.NewRecorder()
+
+		NewLoanHandler(mockService, logger).RemoveScheduleAdjustment(rec, withParams(httptest.NewRequest(http.MethodDelete, "/loans/7/adjustments/x", nil),
+			[]string{"loanID", "adjustmentID"}, []string{"7", "x"}))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestLoanHandlerPrepay(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	withLoanID := func(req *http.Request) *http.Request {
+		return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, &chi.Context{
+			URLParams: chi.RouteParams{Keys: []string{"loanID"}, Values: []string{"7"}},
+		}))
+	}
+	details := loan.PaymentDetails{Channel: loan.ChannelBankTransfer, Reference: "TRX-9"}
+	plan := &loan.Reamortization{
+		Prepayment: loan.Prepayment{ID: 6, LoanID: 7, Option: loan.PrepaymentReduceTerm, Amount: 100, AfterWeek: 1, Principal: 100,
+			PreviousTermWeeks: 3, PreviousWeeklyPaymentAmount: 110, PreviousTotalLoanAmount: 330,
+			TermWeeks: 2, WeeklyPaymentAmount: 110, TotalLoanAmount: 320, Channel: loan.ChannelBankTransfer},
+		Changes: []loan.ScheduleChange{{
+			Kind: loan.ScheduleEntryRemoved, WeekNumber: 3,
+			Before: &loan.ScheduleEntry{ID: 3, WeekNumber: 3, DueDate: time.Date(2025, 1, 27, 0, 0, 0, 0, time.UTC), DueAmount: 110, Status: loan.PaymentStatusPending},
+		}},
+	}
+	body := `{"amount":"100.00","option":"REDUCE_TERM","channel":"BANK_TRANSFER","reference":"TRX-9"}`
+
+	t.Run("records the prepayment", func(t *testing.T) {
+		mockService := new(MockLoanService)
+		mockService.On("Prepay", mock.Anything, int64(7), 100.0, loan.PrepaymentReduceTerm, details, "").Return(plan, nil).Once()
+		rec := httptest.NewRecorder()
+
+		NewLoanHandler(mockService, logger).Prepay(rec, withLoanID(httptest.NewRequest(http.MethodPost, "/loans/7/prepayments", strings.NewReader(body))))
+
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		var resp dto.ReamortizationResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		assert.Equal(t, "6", resp.Prepayment.ID)
+		assert.Equal(t, 2, resp.Prepayment.TermWeeks)
+		assert.Equal(t, "320.00", resp.Prepayment.TotalLoanAmount)
+		require.Len(t, resp.Changes, 1)
+		assert.Equal(t, "REMOVED", resp.Changes[0].Kind)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("rejects an unknown option", func(t *testing.T) {
+		mockService := new(MockLoanService)
+		rec := httptest.NewRecorder()
+
+		NewLoanHandler(mockService, logger).Prepay(rec, withLoanID(httptest.NewRequest(http.MethodPost, "/loans/7/prepayments",
+			strings.NewReader(`{"amount":"100.00","option":"SKIP"}`))))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		mockService.AssertNotCalled(t, "Prepay", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("returns 409 for a loan on hold", func(t *testing.T) {
+		mockService := new(MockLoanService)
+		mockService.On("Prepay", mock.Anything, int64(7), 100.0, loan.PrepaymentReduceTerm, details, "").Return(nil, apperrors.ErrLoanOnHold).Once()
+		rec := httptest.NewRecorder()
+
+		NewLoanHandler(mockService, logger).Prepay(rec, withLoanID(httptest.NewRequest(http.MethodPost, "/loans/7/prepayments", strings.NewReader(body))))
+
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+}
+
+func TestLoanHandlerPreviewRestructure(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	withLoanID := func(req *http.Request) *http.Request {
+		return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, &chi.Context{
+			URLParams: chi.RouteParams{Keys: []string{"loanID"}, Values: []string{"7"}},
+		}))
+	}
+	holiday := loan.Restructure{Kind: loan.RestructurePaymentHoliday, StartsOn: time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC), Weeks: 2}
+	preview := &loan.RestructurePreview{
+		LoanID:      7,
+		Restructure: holiday,
+		Changes: []loan.ScheduleChange{{
+			Kind: loan.ScheduleEntryUpdated, WeekNumber: 3,
+			Before: &loan.ScheduleEntry{ID: 3, WeekNumber: 3, DueDate: time.Date(2025, 1, 27, 0, 0, 0, 0, time.UTC), DueAmount: 110, Status: loan.PaymentStatusPending},
+			After:  &loan.ScheduleEntry{ID: 3, WeekNumber: 3, DueDate: time.Date(2025, 2, 10, 0, 0, 0, 0, time.UTC), DueAmount: 110, Status: loan.PaymentStatusPending},
+		}},
+		Before: loan.LoanTerms{InterestRate: 0.1, TermWeeks: 3, WeeklyPaymentAmount: 110, TotalLoanAmount: 330, TotalInterest: 30, EndDate: time.Date(2025, 1, 27, 0, 0, 0, 0, time.UTC)},
+		After:  loan.LoanTerms{InterestRate: 0.1, TermWeeks: 3, WeeklyPaymentAmount: 110, TotalLoanAmount: 330, TotalInterest: 30, EndDate: time.Date(2025, 2, 10, 0, 0, 0, 0, time.UTC)},
+	}
+
+	t.Run("returns the diff", func(t *testing.T) {
+		mockService := new(MockLoanService)
+		mockService.On("PreviewRestructure", mock.Anything, int64(7), holiday).Return(preview, nil).Once()
+		rec := httptest.NewRecorder()
+
+		NewLoanHandler(mockService, logger).PreviewRestructure(rec, withLoanID(httptest.NewRequest(http.MethodPost, "/loans/7/restructure/preview",
+			strings.NewReader(`{"kind":"PAYMENT_HOLIDAY","startsOn":"2025-01-20","weeks":2,"amount":"ignored"}`))))
+
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var resp dto.RestructurePreviewResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		assert.Equal(t, "PAYMENT_HOLIDAY", resp.Kind)
+		assert.Equal(t, "2025-01-27", resp.Before.EndDate)
+		assert.Equal(t, "2025-02-10", resp.After.EndDate)
+		assert.Equal(t, "30.00", resp.After.TotalInterest)
+		require.Len(t, resp.Changes, 1)
+		assert.Equal(t, "2025-02-10", resp.Changes[0].After.DueDate)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("reads the fields of the kind", func(t *testing.T) {
+		mockService := new(MockLoanService)
+		mockService.On("PreviewRestructure", mock.Anything, int64(7), loan.Restructure{Kind: loan.RestructurePrepayment, Amount: 100, Option: loan.PrepaymentReduceTerm}).Return(preview, nil).Once()
+		rec := httptest.NewRecorder()
+
+		NewLoanHandler(mockService, logger).PreviewRestructure(rec, withLoanID(httptest.NewRequest(http.MethodPost, "/loans/7/restructure/preview",
+			strings.NewReader(`{"kind":"PREPAYMENT","amount":"100.00","option":"REDUCE_TERM","weeks":4}`))))
+
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("rejects an unknown kind", func(t *testing.T) {
+		mockService := new(MockLoanService)
+		rec := httptest.NewRecorder()
+
+		NewLoanHandler(mockService, logger).PreviewRestructure(rec, withLoanID(httptest.NewRequest(http.MethodPost, "/loans/7/restructure/preview",
+			strings.NewReader(`{"kind":"REFINANCE"}`))))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		mockService.AssertNotCalled(t, "PreviewRestructure", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("returns 409 when the change would be refused", func(t *testing.T) {
+		mockService := new(MockLoanService)
+		mockService.On("PreviewRestructure", mock.Anything, int64(7), holiday).Return(nil, apperrors.ErrConflict).Once()
+		rec := httptest.NewRecorder()
+
+		NewLoanHandler(mockService, logger).PreviewRestructure(rec, withLoanID(httptest.NewRequest(http.MethodPost, "/loans/7/restructure/preview",
+			strings.NewReader(`{"kind":"PAYMENT_HOLIDAY","startsOn":"2025-01-20","weeks":2}`))))
+
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+}
+
+func TestLoanHandlerFees(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	withParams := func(req *http.Request, keys, values []string) *http.Request {
+		return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, &chi.Context{
+			URLParams: chi.RouteParams{Keys: keys, Values: values},
+		}))
+	}
+	postedAt := time.Date(2025, 1, 14, 9, 0, 0, 0, time.UTC)
+	fee := &loan.Fee{ID: 4, LoanID: 7, Type: loan.FeeBounce, Amount: 25, Reason: "direct debit returned", PostedAt: postedAt, Waivers: []loan.FeeWaiver{}}
+
+	t.Run("lists the catalog", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+
+		NewLoanHandler(new(MockLoanService), logger).ListFeeTypes(rec, httptest.NewRequest(http.MethodGet, "/loans/fee-types", nil))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		var resp []dto.FeeTypeResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		require.Len(t, resp, 4)
+		assert.Equal(t, "PROCESSING", resp[0].Type)
+	})
+
+	t.Run("posts a fee", func(t *testing.T) {
+		mockService := new(MockLoanService)
+		mockService.On("PostFee", mock.Anything, int64(7), loan.FeeBounce, 25.0, "direct debit returned", "").Return(fee, nil).Once()
+		rec := httptest.NewRecorder()
+
+		NewLoanHandler(mockService, logger).PostFee(rec, withParams(httptest.NewRequest(http.MethodPost, "/loans/7/fees",
+			strings.NewReader(`{"type":"BOUNCE","amount":"25.00","reason":"direct debit returned"}`)), []string{"loanID"}, []string{"7"}))
+
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		var resp dto.FeeResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		assert.Equal(t, "4", resp.ID)
+		assert.Equal(t, "25.00", resp.Amount)
+		assert.False(t, resp.Waived)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("rejects a malformed amount", func(t *testing.T) {
+		mockService := new(MockLoanService)
+		rec := httptest.NewRecorder()
+
+		NewLoanHandler(mockService, logger).PostFee(rec, withParams(httptest.NewRequest(http.MethodPost, "/loans/7/fees",
+			strings.NewReader(`{"type":"BOUNCE","amount":"lots","reason":"returned"}`)), []string{"loanID"}, []string{"7"}))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		mockService.AssertNotCalled(t, "PostFee", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("requests a waiver", func(t *testing.T) {
+		mockService := new(MockLoanService)
+		mockService.On("RequestFeeWaiver", mock.Anything, int64(7), int64(4), "bank error", "").
+			Return(&loan.FeeWaiver{ID: 9, FeeID: 4, LoanID: 7, Reason: "bank error", Status: loan.WaiverPending, RequestedAt: postedAt}, nil).Once()
+		rec := httptest.NewRecorder()
+
+		NewLoanHandler(mockService, logger).RequestFeeWaiver(rec, withParams(httptest.NewRequest(http.MethodPost, "/loans/7/fees/4/waiver",
+			strings.NewReader(`{"reason":"bank error"}`)), []string{"loanID", "feeID"}, []string{"7", "4"}))
+
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		var resp dto.FeeWaiverResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		assert.Equal(t, "PENDING", resp.Status)
+		assert.Nil(t, resp.DecidedAt)
+	})
+
+	t.Run("approves and rejects waivers", func(t *testing.T) {
+		for approve, status := range map[bool]loan.WaiverStatus{true: loan.WaiverApproved, false: loan.WaiverRejected} {
+			mockService := new(MockLoanService)
+			mockService.On("DecideFeeWaiver", mock.Anything, int64(7), int64(4), approve, "").
+				Return(&loan.FeeWaiver{ID: 9, FeeID: 4, LoanID: 7, Status: status, RequestedAt: postedAt, DecidedAt: &postedAt}, nil).Once()
+			handler := NewLoanHandler(mockService, logger)
+			decide := handler.RejectFeeWaiver
+			if approve {
+				decide = handler.ApproveFeeWaiver
+			}
+			rec := httptest.NewRecorder()
+
+			decide(rec, withParams(httptest.NewRequest(http.MethodPost, "/loans/7/fees/4/waiver/x", nil), []string{"loanID", "feeID"}, []string{"7", "4"}))
+
+			require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+			var resp dto.FeeWaiverResponse
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+			assert.Equal(t, string(status), resp.Status)
+			mockService.AssertExpectations(t)
+		}
+	})
+
+	t.Run("returns 403 when the requester decides", func(t *testing.T) {
+		mockService := new(MockLoanService)
+		mockService.On("DecideFeeWaiver", mock.Anything, int64(7), int64(4), true, "").Return(nil, apperrors.ErrForbidden).Once()
+		rec := httptest.NewRecorder()
+
+		NewLoanHandler(mockService, logger).ApproveFeeWaiver(rec, withParams(httptest.NewRequest(http.MethodPost, "/loans/7/fees/4/waiver/approve", nil),
+			[]string{"loanID", "feeID"}, []string{"7", "4"}))
+
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("lists fees", func(t *testing.T) {
+		mockService := new(MockLoanService)
+		mockService.On("GetFees", mock.Anything, int64(7)).Return([]loan.Fee{*fee}, nil).Once()
+		rec := httptest.NewRecorder()
+
+		NewLoanHandler(mockService, logger).GetFees(rec, withParams(httptest.NewRequest(http.MethodGet, "/loans/7/fees", nil), []string{"loanID"}, []string{"7"}))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		var resp []dto.FeeResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		require.Len(t, resp, 1)
+		assert.NotNil(t, resp[0].Waivers)
+	})
 }
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"billing-engine/internal/api/handler/dto"
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const jobKindLoanRepricing = "loan-repricing"
+
+// LoanRepricingHandler applies base-rate changes to many loans at once.
+type LoanRepricingHandler struct {
+	service loan.LoanService
+	bulk    *BulkRunner
+	logger  *slog.Logger
+}
+
+// repricingRun is what a batch repricing needs to run as a job. ChangedBy is
+// carried along because the job does not run with the request's token.
+type repricingRun struct {
+	Filter        loan.RepricingFilter
+	Rate          float64
+	EffectiveFrom time.Time
+	ChangedBy     string
+}
+
+// NewLoanRepricingHandler reprices in the background, as a job on bulk, when
+// the client sends Prefer: respond-async. A nil bulk always reprices within
+// the request.
+func NewLoanRepricingHandler(s loan.LoanService, bulk *BulkRunner, l *slog.Logger) *LoanRepricingHandler {
+	if s == nil {
+		panic("loan service cannot be nil")
+	}
+	if l == nil {
+		panic("logger cannot be nil")
+	}
+	h := &LoanRepricingHandler{service: s, bulk: bulk, logger: l.With("component", "LoanRepricingHandler")}
+	registerBulkTask(bulk, jobKindLoanRepricing, h.reprice)
+	return h
+}
+
+// RepriceLoans handles POST /admin/loans/repricing
+// @Summary Reprice loans in bulk
+// @Description Applies a base-rate change to every loan that is not paid off or, with currentRate, to those charged that rate today. Each loan is repriced as POST /loans/{loanID}/rate would; loans that cannot take the rate, such as those already paid past effectiveFrom, are listed as skipped. With `Prefer: respond-async` the repricing runs in the background: the answer is 202 with the job to poll at the Location header, whose result is the same report.
+// @Tags Loans
+// @Accept json
+// @Produce json
+// @Param request body dto.RepriceLoansRequest true "New rate, the date it takes effect and the loans it applies to"
+// @Success 200 {object} dto.RepricingReportResponse
+// @Success 202 {object} dto.JobResponse "Repricing continues in the background"
+// @Failure 400 {object} dto.ErrorResponse "Invalid rate or date"
+// @Failure 403 {object} dto.ErrorResponse "Token lacks the admin scope"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Failure 503 {object} dto.ErrorResponse "Too many jobs waiting"
+// @Router /admin/loans/repricing [post]
+// @Security BearerAuth
+func (h *LoanRepricingHandler) RepriceLoans(w http.ResponseWriter, r *http.Request) {
+	var req dto.RepriceLoansRequest
+	if err := decodeJSON(r, &req); err != nil {
+		respondError(w, fmt.Errorf("%w: %w", apperrors.ErrInvalidArgument, err))
+		return
+	}
+	if err := req.Validate(); err != nil {
+		respondError(w, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err))
+		return
+	}
+
+	actor := actorFromContext(r.Context())
+	h.logger.WarnContext(r.Context(), "Batch repricing requested", slog.Float64("rate", req.AnnualInterestRate),
+		slog.String("effectiveFrom", req.EffectiveFrom), slog.String("by", actor))
+	run := repricingRun{Filter: req.Filter(), Rate: req.AnnualInterestRate, EffectiveFrom: req.EffectiveDate(), ChangedBy: actor}
+	respondBulk(h.bulk, w, r, jobKindLoanRepricing, 0, run, h.reprice)
+}
+
+func (h *LoanRepricingHandler) reprice(ctx context.Context, run repricingRun, _ func(int)) (any, error) {
+	report, err := h.service.RepriceLoans(ctx, run.Filter, run.Rate, run.EffectiveFrom, run.ChangedBy)
+	if err != nil {
+		return nil, err
+	}
+	return dto.NewRepricingReportResponse(report), nil
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"billing-engine/internal/api/handler/dto"
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/jobs"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoanRepricingHandlerRepriceLoans(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	effectiveFrom := time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC)
+	baseRate := 0.1
+	report := &loan.RepricingReport{Rate: 0.12, EffectiveFrom: effectiveFrom, Repriced: 2, Skipped: []loan.RepricingSkip{{LoanID: 9, Reason: "week 2 of loan 9 is already paid"}}}
+	body := `{"annualInterestRate":0.12,"effectiveFrom":"2025-01-20","currentRate":0.1}`
+
+	t.Run("reprices within the request", func(t *testing.T) {
+		svc := new(MockLoanService)
+		svc.On("RepriceLoans", mock.Anything, loan.RepricingFilter{CurrentRate: &baseRate}, 0.12, effectiveFrom, "").Return(report, nil).Once()
+		rec := httptest.NewRecorder()
+
+		NewLoanRepricingHandler(svc, nil, logger).RepriceLoans(rec, httptest.NewRequest(http.MethodPost, "/admin/loans/repricing", strings.NewReader(body)))
+
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var resp dto.RepricingReportResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		assert.Equal(t, 2, resp.Repriced)
+		require.Len(t, resp.Skipped, 1)
+		assert.Equal(t, "9", resp.Skipped[0].LoanID)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("rejects a missing date", func(t *testing.T) {
+		svc := new(MockLoanService)
+		rec := httptest.NewRecorder()
+
+		NewLoanRepricingHandler(svc, nil, logger).RepriceLoans(rec, httptest.NewRequest(http.MethodPost, "/admin/loans/repricing", strings.NewReader(`{"annualInterestRate":0.12}`)))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		svc.AssertNotCalled(t, "RepriceLoans", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("passes on service errors", func(t *testing.T) {
+		svc := new(MockLoanService)
+		svc.On("RepriceLoans", mock.Anything, mock.Anything, 0.12, effectiveFrom, "").Return(nil, apperrors.ErrInternalServer).Once()
+		rec := httptest.NewRecorder()
+
+		NewLoanRepricingHandler(svc, nil, logger).RepriceLoans(rec, httptest.NewRequest(http.MethodPost, "/admin/loans/repricing", strings.NewReader(body)))
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+
+	t.Run("reprices in the background when the client prefers so", func(t *testing.T) {
+		svc := new(MockLoanService)
+		svc.On("RepriceLoans", mock.Anything, loan.RepricingFilter{CurrentRate: &baseRate}, 0.12, effectiveFrom, "").Return(report, nil).Once()
+		runner := jobs.NewRunner(jobs.NewMemoryStore(), jobs.Config{Workers: 1, PollInterval: 5 * time.Millisecond}, nil, logger)
+		h := NewLoanRepricingHandler(svc, NewBulkRunner(runner, 0, logger), logger)
+		runner.Start(context.Background())
+		t.Cleanup(func() { _ = runner.Stop(context.Background()) })
+
+		req := httptest.NewRequest(http.MethodPost, "/admin/loans/repricing", strings.NewReader(body))
+		req.Header.Set("Prefer", "respond-async")
+		rec := httptest.NewRecorder()
+		h.RepriceLoans(rec, req)
+
+		require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+		var job dto.JobResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&job))
+		assert.Equal(t, "loan-repricing", job.Kind)
+		require.Eventually(t, func() bool {
+			polled, err := runner.Get(context.Background(), job.ID)
+			return err == nil && polled.Status == jobs.StatusSucceeded
+		}, time.Second, 5*time.Millisecond)
+		polled, err := runner.Get(context.Background(), job.ID)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"interestRate":"0.12","effectiveFrom":"2025-01-20","repriced":2,"skipped":[{"loanId":"9","reason":"week 2 of loan 9 is already paid"}]}`, string(polled.Result))
+		svc.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,147 @@
+package handler
+
+import (
+	mw "billing-engine/internal/api/middleware"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ndjsonContentType = "application/x-ndjson"
+
+	// ndjsonFlushRows is how many lines are buffered before they are pushed
+	// to the client.
+	ndjsonFlushRows = 100
+
+	// ndjsonWriteTimeout replaces the server write timeout while streaming:
+	// an export may take longer than any single response, but a client that
+	// stops reading for this long is dropped.
+	ndjsonWriteTimeout = 30 * time.Second
+
+	// ndjsonRowLimitHeader tells clients how many lines one response holds
+	// at most. Receiving that many means the export continues from the
+	// cursor of the last line.
+	ndjsonRowLimitHeader = "X-Row-Limit"
+)
+
+// errRowLimitReached stops a stream once it has sent as many lines as one
+// response may hold.
+var errRowLimitReached = errors.New("row limit reached")
+
+// wantsNDJSON reports whether the Accept header asks for a line-delimited
+// stream.
+func wantsNDJSON(r *http.Request) bool {
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err == nil && mediaType == ndjsonContentType {
+			return true
+		}
+	}
+	return false
+}
+
+// cursorQueryParam reads the ID after which a stream resumes. It is the id of
+// the last line a client received, 0 when starting from the beginning.
+func cursorQueryParam(r *http.Request) (int64, error) {
+	raw := r.URL.Query().Get("cursor")
+	if raw == "" {
+		return 0, nil
+	}
+	cursor, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || cursor < 0 {
+		return 0, fmt.Errorf("%w: cursor must be a non-negative integer", apperrors.ErrInvalidArgument)
+	}
+	return cursor, nil
+}
+
+type ndjsonWriter struct {
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	enc     *json.Encoder
+	rows    int
+	maxRows int
+	started bool
+}
+
+func (s *ndjsonWriter) start() {
+	if s.started {
+		return
+	}
+	s.w.Header().Set("Content-Type", ndjsonContentType)
+	s.w.Header().Set("Cache-Control", "no-store")
+	if s.maxRows > 0 {
+		s.w.Header().Set(ndjsonRowLimitHeader, strconv.Itoa(s.maxRows))
+	}
+	s.w.WriteHeader(http.StatusOK)
+	s.started = true
+}
+
+func (s *ndjsonWriter) write(v any) error {
+	if s.maxRows > 0 && s.rows >= s.maxRows {
+		return errRowLimitReached
+	}
+	s.start()
+	if s.rows%ndjsonFlushRows == 0 {
+		if err := s.rc.SetWriteDeadline(time.Now().Add(ndjsonWriteTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+	}
+	if err := s.enc.Encode(v); err != nil {
+		return err
+	}
+	s.rows++
+	if s.rows%ndjsonFlushRows == 0 {
+		return s.flush()
+	}
+	return nil
+}
+
+func (s *ndjsonWriter) flush() error {
+	if err := s.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+// streamNDJSON writes one JSON document per line for every value stream
+// emits. Nothing is sent before the first line, so failures up to that point
+// get the usual JSON error response. A failure after that cannot change the
+// status any more; the connection is aborted instead, which clients see as a
+// truncated transfer rather than a complete export.
+//
+// The stream runs on a context without the router's request timeout, since
+// exporting the whole book can take longer. A client that goes away is
+// noticed through the failing writes. With a row limit in the request
+// context the stream ends, successfully, after that many lines.
+func streamNDJSON(w http.ResponseWriter, r *http.Request, logger *slog.Logger, stream func(ctx context.Context, emit func(any) error) error) {
+	out := &ndjsonWriter{w: w, rc: http.NewResponseController(w), enc: json.NewEncoder(w), maxRows: mw.StreamRowLimitFromContext(r.Context())}
+	ctx := context.WithoutCancel(r.Context())
+
+	err := stream(ctx, out.write)
+	if errors.Is(err, errRowLimitReached) {
+		err = nil
+	}
+	if err != nil && !out.started {
+		respondError(w, err)
+		return
+	}
+	if err == nil {
+		// An empty export is still a successful one.
+		out.start()
+		if err = out.flush(); err == nil {
+			return
+		}
+	}
+	logger.ErrorContext(ctx, "NDJSON stream failed", slog.Int("rows", out.rows), slog.Any("error", err))
+	_ = out.flush()
+	panic(http.ErrAbortHandler)
+}
@@ -0,0 +1,354 @@
+package handler
+
+import (
+	"billing-engine/internal/api/handler/dto"
+	mw "billing-engine/internal/api/middleware"
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/domain/note"
+	"billing-engine/internal/pkg/apperrors"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const attachmentFileField = "file"
+
+// NoteHandler serves the notes and attachments routes mounted under both
+// /loans/{loanID} and /customers/{customerID}; the URL parameter present
+// decides the subject.
+type NoteHandler struct {
+	service        note.Service
+	loans          loan.LoanService
+	customers      customer.CustomerService
+	maxUploadBytes int64
+	logger         *slog.Logger
+}
+
+func NewNoteHandler(s note.Service, loans loan.LoanService, customers customer.CustomerService, maxUploadBytes int64, l *slog.Logger) *NoteHandler {
+	if s == nil {
+		panic("note service cannot be nil")
+	}
+	if loans == nil || customers == nil {
+		panic("loan and customer services cannot be nil")
+	}
+	if l == nil {
+		panic("logger cannot be nil")
+	}
+	return &NoteHandler{
+		service:        s,
+		loans:          loans,
+		customers:      customers,
+		maxUploadBytes: maxUploadBytes,
+		logger:         l.With("component", "NoteHandler"),
+	}
+}
+
+func (h *NoteHandler) subjectFromURL(r *http.Request) (note.Subject, error) {
+	if raw := chi.URLParam(r, "loanID"); raw != "" {
+		id, err := resolveURLID(r.Context(), "loanID", raw, h.loans.ResolveLoanID)
+		return note.Subject{Type: note.SubjectLoan, ID: id}, err
+	}
+	if raw := chi.URLParam(r, "customerID"); raw != "" {
+		id, err := resolveURLID(r.Context(), "customerID", raw, h.customers.ResolveCustomerID)
+		return note.Subject{Type: note.SubjectCustomer, ID: id}, err
+	}
+	return note.Subject{}, fmt.Errorf("%w: loanID or customerID not found in URL path", apperrors.ErrInvalidArgument)
+}
+
+// resolveURLID accepts a numeric ID or a public UUID, like the loan and
+// customer handlers do for their own routes.
+func resolveURLID(ctx context.Context, param, raw string, resolve func(context.Context, uuid.UUID) (int64, error)) (int64, error) {
+	if publicID, err := uuid.Parse(raw); err == nil {
+		return resolve(ctx, publicID)
+	}
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("%w: invalid %s format in URL path: %s", apperrors.ErrInvalidArgument, param, raw)
+	}
+	return id, nil
+}
+
+func int64URLParam(r *http.Request, param string) (int64, error) {
+	raw := chi.URLParam(r, param)
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("%w: invalid %s format in URL path: %s", apperrors.ErrInvalidArgument, param, raw)
+	}
+	return id, nil
+}
+
+// actorFromContext names the staff member behind the request for the audit
+// fields. It is empty when authentication is disabled.
+func actorFromContext(ctx context.Context) string {
+	claims, ok := mw.ClaimsFromContext(ctx)
+	if !ok {
+		return ""
+	}
+	if username, _ := claims["username"].(string); username != "" {
+		return username
+	}
+	subject, _ := claims.GetSubject()
+	return subject
+}
+
+// CreateNote handles POST /loans/{loanID}/notes and POST /customers/{customerID}/notes
+// @Summary Add a note
+// @Description Records a free-text note against a loan or customer. The author is taken from the bearer token.
+// @Tags Notes
+// @Accept json
+// @Produce json
+// @Param loanID path string true "Loan ID or public UUID"
+// @Param request body dto.CreateNoteRequest true "Note"
+// @Success 201 {object} dto.NoteResponse "Note created"
+// @Failure 400 {object} dto.ErrorResponse "Invalid request payload"
+// @Failure 404 {object} dto.ErrorResponse "Loan or customer not found"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /loans/{loanID}/notes [post]
+// @Security BearerAuth
+func (h *NoteHandler) CreateNote(w http.ResponseWriter, r *http.Request) {
+	subject, err := h.subjectFromURL(r)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	var req dto.CreateNoteRequest
+	if err := decodeJSON(r, &req); err != nil {
+		h.logger.WarnContext(r.Context(), "Failed to decode request body", slog.Any("error", err))
+		respondError(w, fmt.Errorf("%w: %w", apperrors.ErrInvalidArgument, err))
+		return
+	}
+	if err := req.Validate(); err != nil {
+		respondError(w, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err))
+		return
+	}
+
+	created, err := h.service.AddNote(r.Context(), subject, req.Body, actorFromContext(r.Context()))
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "Service failed to add note", slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, dto.NewNoteResponse(created))
+}
+
+// ListNotes handles GET /loans/{loanID}/notes and GET /customers/{customerID}/notes
+// @Summary List notes
+// @Description Lists the notes of a loan or customer, newest first.
+// @Tags Notes
+// @Produce json
+// @Param loanID path string true "Loan ID or public UUID"
+// @Success 200 {array} dto.NoteResponse "Notes"
+// @Failure 404 {object} dto.ErrorResponse "Loan or customer not found"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /loans/{loanID}/notes [get]
+// @Security BearerAuth
+func (h *NoteHandler) ListNotes(w http.ResponseWriter, r *http.Request) {
+	subject, err := h.subjectFromURL(r)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	notes, err := h.service.ListNotes(r.Context(), subject)
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "Service failed to list notes", slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, dto.NewNoteListResponse(notes))
+}
+
+// DeleteNote handles DELETE /loans/{loanID}/notes/{noteID} and DELETE /customers/{customerID}/notes/{noteID}
+// @Summary Delete a note
+// @Tags Notes
+// @Param loanID path string true "Loan ID or public UUID"
+// @Param noteID path int true "Note ID"
+// @Success 204 "Note deleted"
+// @Failure 404 {object} dto.ErrorResponse "Note not found on this loan or customer"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /loans/{loanID}/notes/{noteID} [delete]
+// @Security BearerAuth
+func (h *NoteHandler) DeleteNote(w http.ResponseWriter, r *http.Request) {
+	subject, err := h.subjectFromURL(r)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	noteID, err := int64URLParam(r, "noteID")
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	if err := h.service.DeleteNote(r.Context(), subject, noteID); err != nil {
+		h.logger.WarnContext(r.Context(), "Service failed to delete note", slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CreateAttachment handles POST /loans/{loanID}/attachments and POST /customers/{customerID}/attachments
+// @Summary Upload an attachment
+// @Description Uploads the "file" field of a multipart form to object storage and records its metadata against a loan or customer.
+// @Tags Notes
+// @Accept multipart/form-data
+// @Produce json
+// @Param loanID path string true "Loan ID or public UUID"
+// @Param file formData file true "File to attach"
+// @Success 201 {object} dto.AttachmentResponse "Attachment stored"
+// @Failure 400 {object} dto.ErrorResponse "Missing, empty or oversized file"
+// @Failure 404 {object} dto.ErrorResponse "Loan or customer not found"
+// @Failure 503 {object} dto.ErrorResponse "Object storage is not configured"
+// @Router /loans/{loanID}/attachments [post]
+// @Security BearerAuth
+func (h *NoteHandler) CreateAttachment(w http.ResponseWriter, r *http.Request) {
+	subject, err := h.subjectFromURL(r)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	upload, err := h.readAttachment(w, r)
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "Rejected attachment upload", slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+
+	created, err := h.service.AddAttachment(r.Context(), subject, upload)
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "Service failed to add attachment", slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusCreated, dto.NewAttachmentResponse(created))
+}
+
+// readAttachment buffers the file part so its exact size is known before it
+// is sent to object storage.
+func (h *NoteHandler) readAttachment(w http.ResponseWriter, r *http.Request) (note.Upload, error) {
+	if h.maxUploadBytes > 0 {
+		// Leave room for the multipart framing around the file.
+		r.Body = http.MaxBytesReader(w, r.Body, h.maxUploadBytes+64<<10)
+	}
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return note.Upload{}, fmt.Errorf("%w: expected a multipart/form-data body: %v", apperrors.ErrInvalidArgument, err)
+	}
+
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			return note.Upload{}, fmt.Errorf("%w: multipart body has no %q field", apperrors.ErrInvalidArgument, attachmentFileField)
+		}
+		if err != nil {
+			return note.Upload{}, h.uploadReadError(err)
+		}
+		if part.FormName() != attachmentFileField {
+			part.Close()
+			continue
+		}
+		defer part.Close()
+
+		reader := io.Reader(part)
+		if h.maxUploadBytes > 0 {
+			reader = io.LimitReader(part, h.maxUploadBytes+1)
+		}
+		content, err := io.ReadAll(reader)
+		if err != nil {
+			return note.Upload{}, h.uploadReadError(err)
+		}
+		if h.maxUploadBytes > 0 && int64(len(content)) > h.maxUploadBytes {
+			return note.Upload{}, fmt.Errorf("%w: file exceeds %d bytes", apperrors.ErrInvalidArgument, h.maxUploadBytes)
+		}
+
+		contentType := part.Header.Get("Content-Type")
+		if contentType == "" && len(content) > 0 {
+			contentType = http.DetectContentType(content)
+		}
+		return note.Upload{
+			FileName:    part.FileName(),
+			ContentType: contentType,
+			Size:        int64(len(content)),
+			Content:     bytes.NewReader(content),
+			UploadedBy:  actorFromContext(r.Context()),
+		}, nil
+	}
+}
+
+func (h *NoteHandler) uploadReadError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return fmt.Errorf("%w: file exceeds %d bytes", apperrors.ErrInvalidArgument, h.maxUploadBytes)
+	}
+	return fmt.Errorf("%w: invalid multipart body: %v", apperrors.ErrInvalidArgument, err)
+}
+
+// ListAttachments handles GET /loans/{loanID}/attachments and GET /customers/{customerID}/attachments
+// @Summary List attachments
+// @Description Lists the attachment metadata of a loan or customer, newest first.
+// @Tags Notes
+// @Produce json
+// @Param loanID path string true "Loan ID or public UUID"
+// @Success 200 {array} dto.AttachmentResponse "Attachments"
+// @Failure 404 {object} dto.ErrorResponse "Loan or customer not found"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /loans/{loanID}/attachments [get]
+// @Security BearerAuth
+func (h *NoteHandler) ListAttachments(w http.ResponseWriter, r *http.Request) {
+	subject, err := h.subjectFromURL(r)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	attachments, err := h.service.ListAttachments(r.Context(), subject)
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "Service failed to list attachments", slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, dto.NewAttachmentListResponse(attachments))
+}
+
+// DeleteAttachment handles DELETE /loans/{loanID}/attachments/{attachmentID} and DELETE /customers/{customerID}/attachments/{attachmentID}
+// @Summary Delete an attachment
+// @Description Deletes the attachment metadata and its stored object.
+// @Tags Notes
+// @Param loanID path string true "Loan ID or public UUID"
+// @Param attachmentID path int true "Attachment ID"
+// @Success 204 "Attachment deleted"
+// @Failure 404 {object} dto.ErrorResponse "Attachment not found on this loan or customer"
+// @Failure 503 {object} dto.ErrorResponse "Object storage is not configured"
+// @Router /loans/{loanID}/attachments/{attachmentID} [delete]
+// @Security BearerAuth
+func (h *NoteHandler) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	subject, err := h.subjectFromURL(r)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	attachmentID, err := int64URLParam(r, "attachmentID")
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	if err := h.service.DeleteAttachment(r.Context(), subject, attachmentID); err != nil {
+		h.logger.WarnContext(r.Context(), "Service failed to delete attachment", slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,208 @@
+package handler_test
+
+import (
+	"billing-engine/internal/api/handler"
+	"billing-engine/internal/api/handler/dto"
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/domain/note"
+	"billing-engine/internal/pkg/apperrors"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockNoteService struct {
+	mock.Mock
+}
+
+func (m *MockNoteService) AddNote(ctx context.Context, subject note.Subject, body, author string) (*note.Note, error) {
+	args := m.Called(ctx, subject, body, author)
+	n, _ := args.Get(0).(*note.Note)
+	return n, args.Error(1)
+}
+
+func (m *MockNoteService) ListNotes(ctx context.Context, subject note.Subject) ([]note.Note, error) {
+	args := m.Called(ctx, subject)
+	notes, _ := args.Get(0).([]note.Note)
+	return notes, args.Error(1)
+}
+
+func (m *MockNoteService) DeleteNote(ctx context.Context, subject note.Subject, noteID int64) error {
+	return m.Called(ctx, subject, noteID).Error(0)
+}
+
+func (m *MockNoteService) AddAttachment(ctx context.Context, subject note.Subject, upload note.Upload) (*note.Attachment, error) {
+	args := m.Called(ctx, subject, upload)
+	a, _ := args.Get(0).(*note.Attachment)
+	return a, args.Error(1)
+}
+
+func (m *MockNoteService) ListAttachments(ctx context.Context, subject note.Subject) ([]note.Attachment, error) {
+	args := m.Called(ctx, subject)
+	attachments, _ := args.Get(0).([]note.Attachment)
+	return attachments, args.Error(1)
+}
+
+func (m *MockNoteService) DeleteAttachment(ctx context.Context, subject note.Subject, attachmentID int64) error {
+	return m.Called(ctx, subject, attachmentID).Error(0)
+}
+
+type stubNoteLoanService struct{ loan.LoanService }
+
+func newNoteHandler(svc note.Service, customers *MockCustomerService, maxUploadBytes int64) *handler.NoteHandler {
+	return handler.NewNoteHandler(svc, stubNoteLoanService{}, customers, maxUploadBytes, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func withURLParams(req *http.Request, params map[string]string) *http.Request {
+	rctx := chi.NewRouteContext()
+	for k, v := range params {
+		rctx.URLParams.Add(k, v)
+	}
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+}
+
+func TestNoteHandlerCreateNote(t *testing.T) {
+	loanSubject := note.Subject{Type: note.SubjectLoan, ID: 42}
+
+	t.Run("creates a loan note", func(t *testing.T) {
+		svc := new(MockNoteService)
+		h := newNoteHandler(svc, new(MockCustomerService), 0)
+		svc.On("AddNote", mock.Anything, loanSubject, "Promised to pay Friday", "").
+			Return(&note.Note{ID: 1, Subject: loanSubject, Body: "Promised to pay Friday", CreatedAt: time.Now()}, nil).Once()
+
+		req := httptest.NewRequest(http.MethodPost, "/loans/42/notes", strings.NewReader(`{"body":"Promised to pay Friday"}`))
+		rec := httptest.NewRecorder()
+		h.CreateNote(rec, withURLParams(req, map[string]string{"loanID": "42"}))
+
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		var resp dto.NoteResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, "loan", resp.SubjectType)
+		assert.Equal(t, "42", resp.SubjectID)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("rejects an empty body", func(t *testing.T) {
+		svc := new(MockNoteService)
+		h := newNoteHandler(svc, new(MockCustomerService), 0)
+
+		req := httptest.NewRequest(http.MethodPost, "/loans/42/notes", strings.NewReader(`{"body":" "}`))
+		rec := httptest.NewRecorder()
+		h.CreateNote(rec, withURLParams(req, map[string]string{"loanID": "42"}))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		svc.AssertNotCalled(t, "AddNote", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestNoteHandlerListNotesByCustomerPublicID(t *testing.T) {
+	svc, customers := new(MockNoteService), new(MockCustomerService)
+	h := newNoteHandler(svc, customers, 0)
+	publicID := uuid.New()
+	subject := note.Subject{Type: note.SubjectCustomer, ID: 5}
+
+	customers.On("ResolveCustomerID", mock.Anything, publicID).Return(int64(5), nil).Once()
+	svc.On("ListNotes", mock.Anything, subject).Return([]note.Note{}, nil).Once()
+
+	req := httptest.NewRequest(http.MethodGet, "/customers/"+publicID.String()+"/notes", nil)
+	rec := httptest.NewRecorder()
+	h.ListNotes(rec, withURLParams(req, map[string]string{"customerID": publicID.String()}))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `[]`, rec.Body.String())
+	svc.AssertExpectations(t)
+}
+
+func TestNoteHandlerDeleteNote(t *testing.T) {
+	svc := new(MockNoteService)
+	h := newNoteHandler(svc, new(MockCustomerService), 0)
+	subject := note.Subject{Type: note.SubjectLoan, ID: 42}
+
+	svc.On("DeleteNote", mock.Anything, subject, int64(3)).Return(nil).Once()
+	svc.On("DeleteNote", mock.Anything, subject, int64(4)).Return(apperrors.ErrNotFound).Once()
+
+	for noteID, want := range map[string]int{"3": http.StatusNoContent, "4": http.StatusNotFound, "x": http.StatusBadRequest} {
+		req := httptest.NewRequest(http.MethodDelete, "/loans/42/notes/"+noteID, nil)
+		rec := httptest.NewRecorder()
+		h.DeleteNote(rec, withURLParams(req, map[string]string{"loanID": "42", "noteID": noteID}))
+		assert.Equal(t, want, rec.Code, "noteID %s", noteID)
+	}
+}
+
+func multipartFile(t *testing.T, field, filename, content string) (string, *bytes.Buffer) {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile(field, filename)
+	require.NoError(t, err)
+	io.WriteString(part, content)
+	require.NoError(t, writer.Close())
+	return writer.FormDataContentType(), &body
+}
+
+func TestNoteHandlerCreateAttachment(t *testing.T) {
+	subject := note.Subject{Type: note.SubjectLoan, ID: 42}
+
+	t.Run("uploads the file field", func(t *testing.T) {
+		svc := new(MockNoteService)
+		h := newNoteHandler(svc, new(MockCustomerService), 1024)
+		svc.On("AddAttachment", mock.Anything, subject, mock.MatchedBy(func(u note.Upload) bool {
+			return u.FileName == "contract.pdf" && u.Size == 8 && u.ContentType == "application/octet-stream"
+		})).Return(&note.Attachment{ID: 9, Subject: subject, FileName: "contract.pdf", SizeBytes: 8}, nil).Once()
+
+		contentType, body := multipartFile(t, "file", "contract.pdf", "%PDF-1.7")
+		req := httptest.NewRequest(http.MethodPost, "/loans/42/attachments", body)
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		h.CreateAttachment(rec, withURLParams(req, map[string]string{"loanID": "42"}))
+
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		var resp dto.AttachmentResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, "9", resp.ID)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("rejects oversized files", func(t *testing.T) {
+		svc := new(MockNoteService)
+		h := newNoteHandler(svc, new(MockCustomerService), 4)
+
+		contentType, body := multipartFile(t, "file", "contract.pdf", "%PDF-1.7")
+		req := httptest.NewRequest(http.MethodPost, "/loans/42/attachments", body)
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		h.CreateAttachment(rec, withURLParams(req, map[string]string{"loanID": "42"}))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "exceeds 4 bytes")
+		svc.AssertNotCalled(t, "AddAttachment", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("reports missing object storage", func(t *testing.T) {
+		svc := new(MockNoteService)
+		h := newNoteHandler(svc, new(MockCustomerService), 1024)
+		svc.On("AddAttachment", mock.Anything, subject, mock.Anything).Return(nil, apperrors.ErrUnavailable).Once()
+
+		contentType, body := multipartFile(t, "file", "contract.pdf", "%PDF-1.7")
+		req := httptest.NewRequest(http.MethodPost, "/loans/42/attachments", body)
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		h.CreateAttachment(rec, withURLParams(req, map[string]string{"loanID": "42"}))
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	})
+}
@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"billing-engine/internal/api/handler/dto"
+	mw "billing-engine/internal/api/middleware"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/ratelimit"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	defaultTopConsumers = 20
+	maxTopConsumers     = 1000
+)
+
+var errAccessListsDisabled = fmt.Errorf("%w: rate limit access lists are not configured", apperrors.ErrUnavailable)
+
+// RateLimitHandler shows who the rate limiter sees most and manages the
+// client IPs it blocks or lets through unlimited. access is nil when no
+// access lists are configured and list requests are refused.
+type RateLimitHandler struct {
+	limiter *mw.RateLimiterMiddleware
+	access  *ratelimit.AccessList
+	logger  *slog.Logger
+}
+
+func NewRateLimitHandler(limiter *mw.RateLimiterMiddleware, access *ratelimit.AccessList, l *slog.Logger) *RateLimitHandler {
+	if limiter == nil {
+		panic("rate limiter cannot be nil")
+	}
+	if l == nil {
+		panic("logger cannot be nil")
+	}
+	return &RateLimitHandler{limiter: limiter, access: access, logger: l.With("component", "RateLimitHandler")}
+}
+
+// TopConsumers handles GET /admin/ratelimit/consumers
+// @Summary List the busiest clients
+// @Description Lists the client IPs with the most requests, busiest first, with how many were allowed and how many were rate limited. A client's counts start over after ten idle minutes. Blocked and allowlisted clients are not counted.
+// @Tags Rate Limiting
+// @Produce json
+// @Param limit query int false "Maximum number of clients, default 20, at most 1000"
+// @Success 200 {array} dto.RateLimitConsumerResponse
+// @Failure 400 {object} dto.ErrorResponse "Invalid limit"
+// @Failure 403 {object} dto.ErrorResponse "Token lacks the admin scope"
+// @Router /admin/ratelimit/consumers [get]
+// @Security BearerAuth
+func (h *RateLimitHandler) TopConsumers(w http.ResponseWriter, r *http.Request) {
+	limit := defaultTopConsumers
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxTopConsumers {
+			respondError(w, fmt.Errorf("%w: limit must be between 1 and %d", apperrors.ErrInvalidArgument, maxTopConsumers))
+			return
+		}
+		limit = n
+	}
+	respondJSON(w, http.StatusOK, dto.NewRateLimitConsumerListResponse(h.limiter.TopConsumers(limit)))
+}
+
+// GetLists handles GET /admin/ratelimit/lists
+// @Summary Get the rate limit access lists
+// @Description Returns the blocked client IPs, which are refused with 403, and the allowlisted ones, which skip the rate limit.
+// @Tags Rate Limiting
+// @Produce json
+// @Success 200 {object} dto.RateLimitListsResponse
+// @Failure 403 {object} dto.ErrorResponse "Token lacks the admin scope"
+// @Failure 503 {object} dto.ErrorResponse "Access lists are not configured"
+// @Router /admin/ratelimit/lists [get]
+// @Security BearerAuth
+func (h *RateLimitHandler) GetLists(w http.ResponseWriter, r *http.Request) {
+	if h.access == nil {
+		respondError(w, errAccessListsDisabled)
+		return
+	}
+	respondJSON(w, http.StatusOK, dto.NewRateLimitListsResponse(h.access.Entries()))
+}
+
+// AddToBlocklist handles PUT /admin/ratelimit/blocklist/{principal}
+// @Summary Block a client
+// @Description Refuses every request from the client IP with 403 and takes it off the allowlist. Other instances apply the change on their next refresh.
+// @Tags Rate Limiting
+// @Produce json
+// @Param principal path string true "Client IP address"
+// @Success 200 {object} dto.RateLimitListsResponse
+// @Failure 400 {object} dto.ErrorResponse "Not an IP address"
+// @Failure 403 {object} dto.ErrorResponse "Token lacks the admin scope"
+// @Failure 503 {object} dto.ErrorResponse "Access lists are not configured or Redis is unavailable"
+// @Router /admin/ratelimit/blocklist/{principal} [put]
+// @Security BearerAuth
+func (h *RateLimitHandler) AddToBlocklist(w http.ResponseWriter, r *http.Request) {
+	h.change(w, r, ratelimit.Blocklist, true)
+}
+
+// RemoveFromBlocklist handles DELETE /admin/ratelimit/blocklist/{principal}
+// @Summary Unblock a client
+// @Description Takes the client IP off the blocklist. Unblocking a client that is not blocked succeeds.
+// @Tags Rate Limiting
+// @Produce json
+// @Param principal path string true "Client IP address"
+// @Success 200 {object} dto.RateLimitListsResponse
+// @Failure 400 {object} dto.ErrorResponse "Not an IP address"
+// @Failure 403 {object} dto.ErrorResponse "Token lacks the admin scope"
+// @Failure 503 {object} dto.ErrorResponse "Access lists are not configured or Redis is unavailable"
+// @Router /admin/ratelimit/blocklist/{principal} [delete]
+// @Security BearerAuth
+func (h *RateLimitHandler) RemoveFromBlocklist(w http.ResponseWriter, r *http.Request) {
+	h.change(w, r, ratelimit.Blocklist, false)
+}
+
+// AddToAllowlist handles PUT /admin/ratelimit/allowlist/{principal}
+// @Summary Exempt a client from the rate limit
+// @Description Lets every request from the client IP through without counting it against the limit, and takes it off the blocklist.
+// @Tags Rate Limiting
+// @Produce json
+// @Param principal path string true "Client IP address"
+// @Success 200 {object} dto.RateLimitListsResponse
+// @Failure 400 {object} dto.ErrorResponse "Not an IP address"
+// @Failure 403 {object} dto.ErrorResponse "Token lacks the admin scope"
+// @Failure 503 {object} dto.ErrorResponse "Access lists are not configured or Redis is unavailable"
+// @Router /admin/ratelimit/allowlist/{principal} [put]
+// @Security BearerAuth
+func (h *RateLimitHandler) AddToAllowlist(w http.ResponseWriter, r *http.Request) {
+	h.change(w, r, ratelimit.Allowlist, true)
+}
+
+// RemoveFromAllowlist handles DELETE /admin/ratelimit/allowlist/{principal}
+// @Summary Rate limit a client again
+// @Description Takes the client IP off the allowlist so its requests count against the limit again.
+// @Tags Rate Limiting
+// @Produce json
+// @Param principal path string true "Client IP address"
+// @Success 200 {object} dto.RateLimitListsResponse
+// @Failure 400 {object} dto.ErrorResponse "Not an IP address"
+// @Failure 403 {object} dto.ErrorResponse "Token lacks the admin scope"
+// @Failure 503 {object} dto.ErrorResponse "Access lists are not configured or Redis is unavailable"
+// @Router /admin/ratelimit/allowlist/{principal} [delete]
+// @Security BearerAuth
+func (h *RateLimitHandler) RemoveFromAllowlist(w http.ResponseWriter, r *http.Request) {
+	h.change(w, r, ratelimit.Allowlist, false)
+}
+
+func (h *RateLimitHandler) change(w http.ResponseWriter, r *http.Request, list ratelimit.List, add bool) {
+	if h.access == nil {
+		respondError(w, errAccessListsDisabled)
+		return
+	}
+	principal, err := url.PathUnescape(chi.URLParam(r, "principal"))
+	if err != nil {
+		respondError(w, fmt.Errorf("%w: invalid principal", apperrors.ErrInvalidArgument))
+		return
+	}
+
+	change := h.access.Remove
+	action := "removed from"
+	if add {
+		change = h.access.Add
+		action = "added to"
+	}
+	if err := change(r.Context(), list, principal); err != nil {
+		respondError(w, err)
+		return
+	}
+	h.logger.WarnContext(r.Context(), "Rate limit access list changed",
+		slog.String("principal", principal), slog.String("action", action), slog.String("list", string(list)),
+		slog.String("by", actorFromContext(r.Context())))
+	respondJSON(w, http.StatusOK, dto.NewRateLimitListsResponse(h.access.Entries()))
+}
@@ -0,0 +1,102 @@
+package handler_test
+
+import (
+	"billing-engine/internal/api/handler"
+	"billing-engine/internal/api/handler/dto"
+	mw "billing-engine/internal/api/middleware"
+	"billing-engine/internal/config"
+	"billing-engine/internal/ratelimit"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRateLimitRouter(access *ratelimit.AccessList) (*chi.Mux, *mw.RateLimiterMiddleware) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	limiter := mw.NewRateLimiterMiddleware(config.RateLimitConfig{Enabled: true, RPS: 1, Burst: 1}, access, logger)
+	h := handler.NewRateLimitHandler(limiter, access, logger)
+	r := chi.NewRouter()
+	r.Get("/admin/ratelimit/consumers", h.TopConsumers)
+	r.Get("/admin/ratelimit/lists", h.GetLists)
+	r.Put("/admin/ratelimit/blocklist/{principal}", h.AddToBlocklist)
+	r.Delete("/admin/ratelimit/blocklist/{principal}", h.RemoveFromBlocklist)
+	r.Put("/admin/ratelimit/allowlist/{principal}", h.AddToAllowlist)
+	r.Delete("/admin/ratelimit/allowlist/{principal}", h.RemoveFromAllowlist)
+	return r, limiter
+}
+
+func TestRateLimitHandlerLists(t *testing.T) {
+	access := ratelimit.NewAccessList(ratelimit.NewMemoryStore(), 0, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	r, _ := newRateLimitRouter(access)
+	call := func(method, path string) (*httptest.ResponseRecorder, dto.RateLimitListsResponse) {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		var resp dto.RateLimitListsResponse
+		if rec.Code == http.StatusOK {
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		}
+		return rec, resp
+	}
+
+	rec, resp := call(http.MethodPut, "/admin/ratelimit/allowlist/10.0.0.7")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []string{"10.0.0.7"}, resp.Allowlist)
+
+	rec, resp = call(http.MethodPut, "/admin/ratelimit/blocklist/10.0.0.7")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []string{"10.0.0.7"}, resp.Blocklist)
+	assert.Empty(t, resp.Allowlist)
+
+	rec, resp = call(http.MethodDelete, "/admin/ratelimit/blocklist/10.0.0.7")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, resp.Blocklist)
+
+	rec, _ = call(http.MethodPut, "/admin/ratelimit/blocklist/not-an-ip")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec, resp = call(http.MethodGet, "/admin/ratelimit/lists")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotNil(t, resp.Blocklist, "empty lists are sent as [], not null")
+	assert.NotNil(t, resp.Allowlist)
+}
+
+func TestRateLimitHandlerWithoutAccessLists(t *testing.T) {
+	r, _ := newRateLimitRouter(nil)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/ratelimit/lists", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
+
+func TestRateLimitHandlerTopConsumers(t *testing.T) {
+	r, limiter := newRateLimitRouter(nil)
+	next := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for _, ip := range []string{"198.51.100.4", "198.51.100.4", "198.51.100.5"} {
+		req := httptest.NewRequest(http.MethodGet, "/loans", nil)
+		req.RemoteAddr = ip + ":40000"
+		next.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/ratelimit/consumers?limit=1", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp []dto.RateLimitConsumerResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	require.Len(t, resp, 1)
+	assert.Equal(t, "198.51.100.4", resp[0].Principal)
+	assert.Equal(t, uint64(2), resp[0].Requests)
+	assert.Equal(t, uint64(1), resp[0].Limited)
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/ratelimit/consumers?limit=0", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
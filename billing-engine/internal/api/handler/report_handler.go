@@ -0,0 +1,211 @@
+package handler
+
+import (
+	"billing-engine/internal/api/handler/dto"
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const defaultHistoryDays = 30
+
+// ReportHandler answers point-in-time questions from the daily loan
+// snapshots.
+type ReportHandler struct {
+	snapshots loan.SnapshotService
+	loans     loan.LoanService
+	clock     clock.Clock
+	logger    *slog.Logger
+}
+
+// NewReportHandler builds the handler. clk decides the default date of the
+// reports, which is the billing day in sandbox mode.
+func NewReportHandler(s loan.SnapshotService, loans loan.LoanService, clk clock.Clock, l *slog.Logger) *ReportHandler {
+	if s == nil || loans == nil {
+		panic("snapshot and loan services cannot be nil")
+	}
+	if l == nil {
+		panic("logger cannot be nil")
+	}
+	return &ReportHandler{snapshots: s, loans: loans, clock: clock.OrSystem(clk), logger: l.With("component", "ReportHandler")}
+}
+
+func dateQueryParam(r *http.Request, name string, fallback time.Time) (time.Time, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return fallback, nil
+	}
+	date, err := time.Parse(time.DateOnly, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %s must be a date in YYYY-MM-DD format", apperrors.ErrInvalidArgument, name)
+	}
+	return date, nil
+}
+
+func (h *ReportHandler) today() time.Time {
+	y, m, d := h.clock.Now().UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// GetLoanHistory handles GET /loans/{loanID}/history
+// @Summary Get loan status history
+// @Description Returns the daily snapshots of a loan's status, outstanding amount and days past due between from and to, inclusive. Defaults to the last 30 days.
+// @Tags Loans
+// @Produce json
+// @Param loanID path string true "Numeric ID or public UUID"
+// @Param from query string false "First day, YYYY-MM-DD"
+// @Param to query string false "Last day, YYYY-MM-DD"
+// @Success 200 {object} dto.LoanHistoryResponse
+// @Failure 400 {object} dto.ErrorResponse "Invalid loan ID or date range"
+// @Failure 404 {object} dto.ErrorResponse "Loan not found"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /loans/{loanID}/history [get]
+// @Security BearerAuth
+func (h *ReportHandler) GetLoanHistory(w http.ResponseWriter, r *http.Request) {
+	loanID, err := resolveURLID(r.Context(), "loanID", chi.URLParam(r, "loanID"), h.loans.ResolveLoanID)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	to, err := dateQueryParam(r, "to", h.today())
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	from, err := dateQueryParam(r, "from", to.AddDate(0, 0, -defaultHistoryDays))
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	snapshots, err := h.snapshots.LoanHistory(r.Context(), loanID, from, to)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to get loan history", slog.Int64("loanID", loanID), slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, dto.NewLoanHistoryResponse(loanID, from, to, snapshots))
+}
+
+// GetPortfolio handles GET /reports/portfolio
+// @Summary Get the portfolio at a date
+// @Description Aggregates the loan book by status and days-past-due bucket from the latest snapshot on or before date. Defaults to today. With `Accept: application/x-ndjson`, streams the snapshot of every loan instead, one per line in loan ID order; pass the `loanId` of the last line received as `cursor` to resume.
+// @Tags Reports
+// @Produce json
+// @Produce x-ndjson
+// @Param date query string false "Report date, YYYY-MM-DD"
+// @Param cursor query int false "Stream only loans with an ID above this one"
+// @Success 200 {object} dto.PortfolioResponse
+// @Failure 400 {object} dto.ErrorResponse "Invalid date or cursor"
+// @Failure 404 {object} dto.ErrorResponse "No snapshot on or before the date"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /reports/portfolio [get]
+// @Security BearerAuth
+func (h *ReportHandler) GetPortfolio(w http.ResponseWriter, r *http.Request) {
+	date, err := dateQueryParam(r, "date", h.today())
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	if wantsNDJSON(r) {
+		h.streamPortfolio(w, r, date)
+		return
+	}
+
+	report, err := h.snapshots.PortfolioAt(r.Context(), date)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to build portfolio report", slog.String("date", date.Format(time.DateOnly)), slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, dto.NewPortfolioResponse(report))
+}
+
+func (h *ReportHandler) streamPortfolio(w http.ResponseWriter, r *http.Request, date time.Time) {
+	cursor, err := cursorQueryParam(r)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	streamNDJSON(w, r, h.logger, func(ctx context.Context, emit func(any) error) error {
+		return h.snapshots.StreamPortfolio(ctx, date, cursor, func(s loan.Snapshot) error {
+			return emit(dto.NewPortfolioLoanResponse(s))
+		})
+	})
+}
+
+// GetCollectionsByChannel handles GET /reports/collections-by-channel
+// @Summary Get collections by payment channel
+// @Description Totals the payments received between from and to, inclusive, by channel, for reconciliation. Every channel is listed; UNSPECIFIED covers payments posted without one, including those made before channels were recorded. Both dates default to today.
+// @Tags Reports
+// @Produce json
+// @Param from query string false "First day, YYYY-MM-DD"
+// @Param to query string false "Last day, YYYY-MM-DD"
+// @Success 200 {object} dto.CollectionsByChannelResponse
+// @Failure 400 {object} dto.ErrorResponse "Invalid date range"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /reports/collections-by-channel [get]
+// @Security BearerAuth
+func (h *ReportHandler) GetCollectionsByChannel(w http.ResponseWriter, r *http.Request) {
+	to, err := dateQueryParam(r, "to", h.today())
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	from, err := dateQueryParam(r, "from", to)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	report, err := h.loans.CollectionsByChannel(r.Context(), from, to)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to build collections report",
+			slog.String("from", from.Format(time.DateOnly)), slog.String("to", to.Format(time.DateOnly)), slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, dto.NewCollectionsByChannelResponse(report))
+}
+
+// GetTaxReport handles GET /reports/tax
+// @Summary Get the tax charged on fees
+// @Description Totals the tax lines of the fees posted between from and to, inclusive, by jurisdiction and fee type. waivedAmount is the tax on fees that have been waived since. Tax on interest is not included, because it is only worked out on the interest still owed. Both dates default to today.
+// @Tags Reports
+// @Produce json
+// @Param from query string false "First day, YYYY-MM-DD"
+// @Param to query string false "Last day, YYYY-MM-DD"
+// @Success 200 {object} dto.TaxReportResponse
+// @Failure 400 {object} dto.ErrorResponse "Invalid date range"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /reports/tax [get]
+// @Security BearerAuth
+func (h *ReportHandler) GetTaxReport(w http.ResponseWriter, r *http.Request) {
+	to, err := dateQueryParam(r, "to", h.today())
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	from, err := dateQueryParam(r, "from", to)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	report, err := h.loans.TaxReport(r.Context(), from, to)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to build tax report",
+			slog.String("from", from.Format(time.DateOnly)), slog.String("to", to.Format(time.DateOnly)), slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, dto.NewTaxReportResponse(report))
+}
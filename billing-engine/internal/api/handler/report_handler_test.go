@@ -0,0 +1,329 @@
+package handler_test
+
+import (
+	"billing-engine/internal/api/handler"
+	"billing-engine/internal/api/handler/dto"
+	mw "billing-engine/internal/api/middleware"
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockSnapshotService struct {
+	mock.Mock
+}
+
+func (m *MockSnapshotService) TakeSnapshots(ctx context.Context, date time.Time) (int64, error) {
+	args := m.Called(ctx, date)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockSnapshotService) LoanHistory(ctx context.Context, loanID int64, from, to time.Time) ([]loan.Snapshot, error) {
+	args := m.Called(ctx, loanID, from, to)
+	snapshots, _ := args.Get(0).([]loan.Snapshot)
+	return snapshots, args.Error(1)
+}
+
+func (m *MockSnapshotService) PortfolioAt(ctx context.Context, date time.Time) (*loan.PortfolioReport, error) {
+	args := m.Called(ctx, date)
+	report, _ := args.Get(0).(*loan.PortfolioReport)
+	return report, args.Error(1)
+}
+
+func (m *MockSnapshotService) StreamPortfolio(ctx context.Context, date time.Time, afterLoanID int64, fn func(loan.Snapshot) error) error {
+	args := m.Called(ctx, date, afterLoanID)
+	snapshots, _ := args.Get(0).([]loan.Snapshot)
+	for _, s := range snapshots {
+		if err := fn(s); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+func newReportHandler(svc loan.SnapshotService) *handler.ReportHandler {
+	return handler.NewReportHandler(svc, stubNoteLoanService{}, clock.System(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestReportHandlerGetLoanHistory(t *testing.T) {
+	from := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)
+
+	t.Run("returns snapshots in range", func(t *testing.T) {
+		svc := new(MockSnapshotService)
+		svc.On("LoanHistory", mock.Anything, int64(42), from, to).Return([]loan.Snapshot{
+			{LoanID: 42, Date: to, Status: loan.StatusDelinquent, Outstanding: 1250.5, DPD: 15},
+		}, nil).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/loans/42/history?from=2025-03-01&to=2025-03-31", nil)
+		rec := httptest.NewRecorder()
+		newReportHandler(svc).GetLoanHistory(rec, withURLParams(req, map[string]string{"loanID": "42"}))
+
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var resp dto.LoanHistoryResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, "2025-03-01", resp.From)
+		require.Len(t, resp.Snapshots, 1)
+		assert.Equal(t, dto.LoanSnapshotResponse{Date: "2025-03-31", Status: "DELINQUENT", Outstanding: "1250.50", DPD: 15}, resp.Snapshots[0])
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("defaults to the last 30 days", func(t *testing.T) {
+		svc := new(MockSnapshotService)
+		svc.On("LoanHistory", mock.Anything, int64(42), mock.Anything, mock.Anything).Return([]loan.Snapshot{}, nil).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/loans/42/history", nil)
+		rec := httptest.NewRecorder()
+		newReportHandler(svc).GetLoanHistory(rec, withURLParams(req, map[string]string{"loanID": "42"}))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		gotFrom, gotTo := svc.Calls[0].Arguments.Get(2).(time.Time), svc.Calls[0].Arguments.Get(3).(time.Time)
+		assert.Equal(t, 30*24*time.Hour, gotTo.Sub(gotFrom))
+	})
+
+	t.Run("rejects malformed dates", func(t *testing.T) {
+		svc := new(MockSnapshotService)
+
+		req := httptest.NewRequest(http.MethodGet, "/loans/42/history?from=31-03-2025", nil)
+		rec := httptest.NewRecorder()
+		newReportHandler(svc).GetLoanHistory(rec, withURLParams(req, map[string]string{"loanID": "42"}))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		svc.AssertNotCalled(t, "LoanHistory", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("unknown loan", func(t *testing.T) {
+		svc := new(MockSnapshotService)
+		svc.On("LoanHistory", mock.Anything, int64(42), from, to).Return(nil, apperrors.ErrNotFound).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/loans/42/history?from=2025-03-01&to=2025-03-31", nil)
+		rec := httptest.NewRecorder()
+		newReportHandler(svc).GetLoanHistory(rec, withURLParams(req, map[string]string{"loanID": "42"}))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestReportHandlerGetPortfolio(t *testing.T) {
+	date := time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)
+
+	t.Run("returns the report", func(t *testing.T) {
+		svc := new(MockSnapshotService)
+		svc.On("PortfolioAt", mock.Anything, date).Return(&loan.PortfolioReport{
+			RequestedDate: date,
+			AsOf:          date.AddDate(0, 0, -1),
+			Loans:         2,
+			Outstanding:   1500,
+			ByStatus:      []loan.PortfolioBucket{{Name: "ACTIVE", Loans: 2, Outstanding: 1500}},
+			ByDPD:         []loan.PortfolioBucket{{Name: "current", Loans: 2, Outstanding: 1500}},
+		}, nil).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/reports/portfolio?date=2025-03-31", nil)
+		rec := httptest.NewRecorder()
+		newReportHandler(svc).GetPortfolio(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.JSONEq(t, `{
+			"date": "2025-03-31", "asOf": "2025-03-30", "loans": 2, "outstanding": "1500.00",
+			"byStatus": [{"name": "ACTIVE", "loans": 2, "outstanding": "1500.00"}],
+			"byDpd": [{"name": "current", "loans": 2, "outstanding": "1500.00"}]
+		}`, rec.Body.String())
+	})
+
+	t.Run("defaults to the day of the clock", func(t *testing.T) {
+		svc := new(MockSnapshotService)
+		svc.On("PortfolioAt", mock.Anything, date).Return(&loan.PortfolioReport{RequestedDate: date, AsOf: date}, nil).Once()
+		h := handler.NewReportHandler(svc, stubNoteLoanService{}, clock.NewFake(date.Add(15*time.Hour)), slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+		rec := httptest.NewRecorder()
+		h.GetPortfolio(rec, httptest.NewRequest(http.MethodGet, "/reports/portfolio", nil))
+
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("no snapshot yet", func(t *testing.T) {
+		svc := new(MockSnapshotService)
+		svc.On("PortfolioAt", mock.Anything, date).Return(nil, apperrors.ErrNotFound).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/reports/portfolio?date=2025-03-31", nil)
+		rec := httptest.NewRecorder()
+		newReportHandler(svc).GetPortfolio(rec, req)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("streams per-loan snapshots as NDJSON", func(t *testing.T) {
+		svc := new(MockSnapshotService)
+		asOf := date.AddDate(0, 0, -1)
+		svc.On("StreamPortfolio", mock.Anything, date, int64(10)).Return([]loan.Snapshot{
+			{LoanID: 11, Date: asOf, Status: loan.StatusActive, Outstanding: 500},
+			{LoanID: 12, Date: asOf, Status: loan.StatusDelinquent, Outstanding: 1000, DPD: 21},
+		}, nil).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/reports/portfolio?date=2025-03-31&cursor=10", nil)
+		req.Header.Set("Accept", "application/x-ndjson")
+		rec := httptest.NewRecorder()
+		newReportHandler(svc).GetPortfolio(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.Equal(t, "application/x-ndjson", rec.Header().Get("Content-Type"))
+		assert.Equal(t, `{"loanId":"11","date":"2025-03-30","status":"ACTIVE","outstanding":"500.00","dpd":0}`+"\n"+
+			`{"loanId":"12","date":"2025-03-30","status":"DELINQUENT","outstanding":"1000.00","dpd":21}`+"\n", rec.Body.String())
+		svc.AssertNotCalled(t, "PortfolioAt", mock.Anything, mock.Anything)
+	})
+
+	t.Run("ends the stream at the row limit", func(t *testing.T) {
+		svc := new(MockSnapshotService)
+		svc.On("StreamPortfolio", mock.Anything, date, int64(0)).Return([]loan.Snapshot{
+			{LoanID: 11, Date: date, Status: loan.StatusActive, Outstanding: 500},
+			{LoanID: 12, Date: date, Status: loan.StatusActive, Outstanding: 500},
+		}, nil).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/reports/portfolio?date=2025-03-31", nil)
+		req.Header.Set("Accept", "application/x-ndjson")
+		rec := httptest.NewRecorder()
+		mw.StreamRowLimit(1)(http.HandlerFunc(newReportHandler(svc).GetPortfolio)).ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.Equal(t, "1", rec.Header().Get("X-Row-Limit"))
+		assert.Equal(t, `{"loanId":"11","date":"2025-03-31","status":"ACTIVE","outstanding":"500.00","dpd":0}`+"\n", rec.Body.String())
+	})
+}
+
+// collectionsLoanService records the range it was asked for.
+type collectionsLoanService struct {
+	loan.LoanService
+	from, to time.Time
+	err      error
+}
+
+func (s *collectionsLoanService) CollectionsByChannel(_ context.Context, from, to time.Time) (*loan.CollectionsReport, error) {
+	s.from, s.to = from, to
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &loan.CollectionsReport{
+		From: from, To: to, TotalPayments: 3, TotalAmount: 330,
+		Channels: []loan.ChannelCollections{
+			{Channel: loan.ChannelCash, Payments: 1, Amount: 110},
+			{Channel: loan.ChannelDirectDebit, Payments: 2, Amount: 220},
+		},
+	}, nil
+}
+
+func (s *collectionsLoanService) TaxReport(_ context.Context, from, to time.Time) (*loan.TaxReport, error) {
+	s.from, s.to = from, to
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &loan.TaxReport{
+		From: from, To: to, TotalAmount: 16.5, TotalWaived: 2.75, TotalPayable: 13.75,
+		Rows: []loan.TaxTotals{
+			{Jurisdiction: "ID", FeeType: loan.FeeBounce, Lines: 2, TaxableAmount: 50, Amount: 5.5, WaivedAmount: 2.75},
+			{Jurisdiction: "ID", FeeType: loan.FeeLegal, Lines: 1, TaxableAmount: 100, Amount: 11},
+		},
+	}, nil
+}
+
+func TestReportHandlerGetCollectionsByChannel(t *testing.T) {
+	today := time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)
+	newHandler := func(loans loan.LoanService) *handler.ReportHandler {
+		return handler.NewReportHandler(new(MockSnapshotService), loans, clock.NewFake(today.Add(15*time.Hour)), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	}
+
+	t.Run("returns the totals by channel", func(t *testing.T) {
+		loans := &collectionsLoanService{}
+		req := httptest.NewRequest(http.MethodGet, "/reports/collections-by-channel?from=2025-03-01&to=2025-03-15", nil)
+		rec := httptest.NewRecorder()
+		newHandler(loans).GetCollectionsByChannel(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.Equal(t, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), loans.from)
+		assert.JSONEq(t, `{
+			"from": "2025-03-01", "to": "2025-03-15", "payments": 3, "amount": "330.00",
+			"byChannel": [
+				{"channel": "CASH", "payments": 1, "amount": "110.00"},
+				{"channel": "DIRECT_DEBIT", "payments": 2, "amount": "220.00"}
+			]
+		}`, rec.Body.String())
+	})
+
+	t.Run("defaults to the day of the clock", func(t *testing.T) {
+		loans := &collectionsLoanService{}
+		rec := httptest.NewRecorder()
+		newHandler(loans).GetCollectionsByChannel(rec, httptest.NewRequest(http.MethodGet, "/reports/collections-by-channel", nil))
+
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.Equal(t, today, loans.from)
+		assert.Equal(t, today, loans.to)
+	})
+
+	t.Run("rejects a malformed date", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		newHandler(&collectionsLoanService{}).GetCollectionsByChannel(rec, httptest.NewRequest(http.MethodGet, "/reports/collections-by-channel?from=March", nil))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("passes on service errors", func(t *testing.T) {
+		loans := &collectionsLoanService{err: fmt.Errorf("%w: from must not be after to", apperrors.ErrInvalidArgument)}
+		rec := httptest.NewRecorder()
+		newHandler(loans).GetCollectionsByChannel(rec, httptest.NewRequest(http.MethodGet, "/reports/collections-by-channel?from=2025-03-02&to=2025-03-01", nil))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestReportHandlerGetTaxReport(t *testing.T) {
+	today := time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)
+	newHandler := func(loans loan.LoanService) *handler.ReportHandler {
+		return handler.NewReportHandler(new(MockSnapshotService), loans, clock.NewFake(today.Add(15*time.Hour)), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	}
+
+	t.Run("returns the totals by jurisdiction and fee type", func(t *testing.T) {
+		loans := &collectionsLoanService{}
+		rec := httptest.NewRecorder()
+		newHandler(loans).GetTaxReport(rec, httptest.NewRequest(http.MethodGet, "/reports/tax?from=2025-03-01&to=2025-03-15", nil))
+
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.Equal(t, time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC), loans.to)
+		assert.JSONEq(t, `{
+			"from": "2025-03-01", "to": "2025-03-15", "amount": "16.50", "waived": "2.75", "payable": "13.75",
+			"rows": [
+				{"jurisdiction": "ID", "feeType": "BOUNCE", "lines": 2, "taxableAmount": "50.00", "amount": "5.50", "waivedAmount": "2.75"},
+				{"jurisdiction": "ID", "feeType": "LEGAL", "lines": 1, "taxableAmount": "100.00", "amount": "11.00", "waivedAmount": "0.00"}
+			]
+		}`, rec.Body.String())
+	})
+
+	t.Run("defaults to the day of the clock", func(t *testing.T) {
+		loans := &collectionsLoanService{}
+		rec := httptest.NewRecorder()
+		newHandler(loans).GetTaxReport(rec, httptest.NewRequest(http.MethodGet, "/reports/tax", nil))
+
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.Equal(t, today, loans.from)
+		assert.Equal(t, today, loans.to)
+	})
+
+	t.Run("rejects a malformed date", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		newHandler(&collectionsLoanService{}).GetTaxReport(rec, httptest.NewRequest(http.MethodGet, "/reports/tax?to=March", nil))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
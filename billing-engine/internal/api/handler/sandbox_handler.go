@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"billing-engine/internal/api/handler/dto"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/sandbox"
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+var errSandboxDisabled = fmt.Errorf("%w: sandbox mode is not enabled", apperrors.ErrUnavailable)
+
+// SandboxHandler serves the billing clock of a sandbox deployment. service
+// is nil outside sandbox mode and every request is refused.
+type SandboxHandler struct {
+	service sandbox.Service
+	logger  *slog.Logger
+}
+
+func NewSandboxHandler(s sandbox.Service, l *slog.Logger) *SandboxHandler {
+	if l == nil {
+		panic("logger cannot be nil")
+	}
+	return &SandboxHandler{service: s, logger: l.With("component", "SandboxHandler")}
+}
+
+// GetClock handles GET /admin/sandbox/clock
+// @Summary Get the sandbox billing clock
+// @Description Returns the billing time of a sandbox deployment and how many days it runs ahead of the wall clock.
+// @Tags Sandbox
+// @Produce json
+// @Success 200 {object} dto.SandboxClockResponse
+// @Failure 403 {object} dto.ErrorResponse "Token lacks the admin scope"
+// @Failure 503 {object} dto.ErrorResponse "Sandbox mode is not enabled"
+// @Router /admin/sandbox/clock [get]
+// @Security BearerAuth
+func (h *SandboxHandler) GetClock(w http.ResponseWriter, r *http.Request) {
+	if h.service == nil {
+		respondError(w, errSandboxDisabled)
+		return
+	}
+	respondJSON(w, http.StatusOK, dto.NewSandboxClockResponse(h.service.State(r.Context())))
+}
+
+// AdvanceClock handles POST /admin/sandbox/clock/advance
+// @Summary Advance the sandbox billing clock
+// @Description Moves the billing clock forward one day at a time and runs the delinquency and snapshot jobs for every simulated day. Time cannot be moved back.
+// @Tags Sandbox
+// @Accept json
+// @Produce json
+// @Param request body dto.AdvanceClockRequest true "Days to simulate"
+// @Success 200 {object} dto.SandboxClockResponse
+// @Failure 400 {object} dto.ErrorResponse "Invalid number of days"
+// @Failure 403 {object} dto.ErrorResponse "Token lacks the admin scope"
+// @Failure 500 {object} dto.ErrorResponse "A job failed; the clock stays on the failed day"
+// @Failure 503 {object} dto.ErrorResponse "Sandbox mode is not enabled"
+// @Router /admin/sandbox/clock/advance [post]
+// @Security BearerAuth
+func (h *SandboxHandler) AdvanceClock(w http.ResponseWriter, r *http.Request) {
+	if h.service == nil {
+		respondError(w, errSandboxDisabled)
+		return
+	}
+	var req dto.AdvanceClockRequest
+	if err := decodeJSON(r, &req); err != nil {
+		respondError(w, fmt.Errorf("%w: %w", apperrors.ErrInvalidArgument, err))
+		return
+	}
+	if err := req.Validate(); err != nil {
+		respondError(w, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err))
+		return
+	}
+
+	h.logger.WarnContext(r.Context(), "Sandbox clock advance requested", slog.Int("days", req.Days), slog.String("by", actorFromContext(r.Context())))
+	state, err := h.service.Advance(r.Context(), req.Days)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "Sandbox clock advance failed", slog.Int("offsetDays", state.OffsetDays), slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, dto.NewSandboxClockResponse(state))
+}
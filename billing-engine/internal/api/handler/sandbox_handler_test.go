@@ -0,0 +1,107 @@
+package handler_test
+
+import (
+	"billing-engine/internal/api/handler"
+	"billing-engine/internal/api/handler/dto"
+	"billing-engine/internal/sandbox"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockSandboxService struct {
+	mock.Mock
+}
+
+func (m *MockSandboxService) State(ctx context.Context) sandbox.State {
+	return m.Called(ctx).Get(0).(sandbox.State)
+}
+
+func (m *MockSandboxService) Advance(ctx context.Context, days int) (sandbox.State, error) {
+	args := m.Called(ctx, days)
+	return args.Get(0).(sandbox.State), args.Error(1)
+}
+
+func newSandboxHandler(svc sandbox.Service) *handler.SandboxHandler {
+	return handler.NewSandboxHandler(svc, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestSandboxHandlerGetClock(t *testing.T) {
+	svc := new(MockSandboxService)
+	now := time.Date(2025, 1, 27, 9, 30, 0, 0, time.UTC)
+	svc.On("State", mock.Anything).Return(sandbox.State{Now: now, OffsetDays: 21})
+
+	rec := httptest.NewRecorder()
+	newSandboxHandler(svc).GetClock(rec, httptest.NewRequest(http.MethodGet, "/admin/sandbox/clock", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp dto.SandboxClockResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.True(t, now.Equal(resp.Now))
+	assert.Equal(t, "2025-01-27", resp.Today)
+	assert.Equal(t, 21, resp.OffsetDays)
+}
+
+func TestSandboxHandlerAdvanceClock(t *testing.T) {
+	now := time.Date(2025, 1, 13, 9, 30, 0, 0, time.UTC)
+
+	t.Run("advances", func(t *testing.T) {
+		svc := new(MockSandboxService)
+		svc.On("Advance", mock.Anything, 7).Return(sandbox.State{Now: now, OffsetDays: 7}, nil)
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/admin/sandbox/clock/advance", strings.NewReader(`{"days":7}`))
+		newSandboxHandler(svc).AdvanceClock(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		var resp dto.SandboxClockResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		assert.Equal(t, 7, resp.OffsetDays)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("rejects invalid days", func(t *testing.T) {
+		svc := new(MockSandboxService)
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/admin/sandbox/clock/advance", strings.NewReader(`{"days":0}`))
+		newSandboxHandler(svc).AdvanceClock(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		svc.AssertNotCalled(t, "Advance", mock.Anything, mock.Anything)
+	})
+
+	t.Run("job failure", func(t *testing.T) {
+		svc := new(MockSandboxService)
+		svc.On("Advance", mock.Anything, 3).Return(sandbox.State{Now: now, OffsetDays: 1}, errors.New("delinquency job failed on 2025-01-07"))
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/admin/sandbox/clock/advance", strings.NewReader(`{"days":3}`))
+		newSandboxHandler(svc).AdvanceClock(rec, req)
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
+
+func TestSandboxHandlerDisabled(t *testing.T) {
+	h := newSandboxHandler(nil)
+
+	rec := httptest.NewRecorder()
+	h.GetClock(rec, httptest.NewRequest(http.MethodGet, "/admin/sandbox/clock", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	rec = httptest.NewRecorder()
+	h.AdvanceClock(rec, httptest.NewRequest(http.MethodPost, "/admin/sandbox/clock/advance", strings.NewReader(`{"days":7}`)))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"billing-engine/internal/api/handler/dto"
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/scope"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+type SelfServiceHandler struct {
+	loanService     loan.LoanService
+	customerService customer.CustomerService
+	logger          *slog.Logger
+}
+
+func NewSelfServiceHandler(ls loan.LoanService, cs customer.CustomerService, l *slog.Logger) *SelfServiceHandler {
+	return &SelfServiceHandler{
+		loanService:     ls,
+		customerService: cs,
+		logger:          l.With("component", "SelfServiceHandler"),
+	}
+}
+
+func (h *SelfServiceHandler) currentCustomer(ctx context.Context) (*customer.Customer, error) {
+	customerID, ok := scope.CustomerFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("%w: missing customer scope", apperrors.ErrUnauthorized)
+	}
+	cust, err := h.customerService.GetCustomer(ctx, customerID)
+	if err != nil {
+		if errors.Is(err, apperrors.ErrNotFound) {
+			return nil, fmt.Errorf("%w: customer %d not found", apperrors.ErrNotFound, customerID)
+		}
+		return nil, err
+	}
+	return cust, nil
+}
+
+func (h *SelfServiceHandler) currentLoanID(ctx context.Context) (int64, error) {
+	cust, err := h.currentCustomer(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if cust.LoanID == nil {
+		return 0, fmt.Errorf("%w: customer %d has no loan", apperrors.ErrNotFound, cust.CustomerID)
+	}
+	return *cust.LoanID, nil
+}
+
+// MyLoans lists the loans of the authenticated customer.
+//
+// @Summary List my loans
+// @Description Returns the loans owned by the customer identified by the customer scoped bearer token.
+// @Tags Self Service
+// @Produce json
+// @Success 200 {array} dto.LoanResponse "Loans owned by the customer"
+// @Failure 401 {object} dto.ErrorResponse "Missing or invalid customer token"
+// @Failure 403 {object} dto.ErrorResponse "Token is not customer scoped"
+// @Failure 404 {object} dto.ErrorResponse "Customer not found"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /me/loans [get]
+// @Security BearerAuth
+func (h *SelfServiceHandler) MyLoans(w http.ResponseWriter, r *http.Request) {
+	cust, err := h.currentCustomer(r.Context())
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	resp := make([]dto.LoanResponse, 0, 1)
+	if cust.LoanID != nil {
+		domainLoan, err := h.loanService.GetLoan(r.Context(), *cust.LoanID, false)
+		if err != nil {
+			respondError(w, err)
+			return
+		}
+		resp = append(resp, dto.NewLoanResponse(domainLoan, false))
+	}
+
+	h.logger.InfoContext(r.Context(), "Customer loans listed", slog.Int64("customerID", cust.CustomerID), slog.Int("count", len(resp)))
+	respondJSON(w, http.StatusOK, resp)
+}
+
+// MySchedule returns the repayment schedule of the authenticated customer's loan.
+//
+// @Summary Retrieve my repayment schedule
+// @Description Returns the repayment schedule of the loan owned by the customer identified by the customer scoped bearer token.
+// @Tags Self Service
+// @Produce json
+// @Param If-Modified-Since header string false "Last-Modified value from an earlier response"
+// @Success 200 {array} dto.ScheduleEntryResponse "Repayment schedule"
+// @Header 200 {string} Last-Modified "When the loan or its schedule last changed"
+// @Success 304 "Nothing changed since If-Modified-Since"
+// @Failure 401 {object} dto.ErrorResponse "Missing or invalid customer token"
+// @Failure 403 {object} dto.ErrorResponse "Token is not customer scoped"
+// @Failure 404 {object} dto.ErrorResponse "Customer or loan not found"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /me/schedule [get]
+// @Security BearerAuth
+func (h *SelfServiceHandler) MySchedule(w http.ResponseWriter, r *http.Request) {
+	loanID, err := h.currentLoanID(r.Context())
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	lastModified, err := h.loanService.GetLastModified(r.Context(), loanID)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	if notModified(w, r, lastModified) {
+		return
+	}
+
+	schedule, err := h.loanService.GetLoanSchedule(r.Context(), loanID)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	resp := make([]dto.ScheduleEntryResponse, len(schedule))
+	for i, entry := range schedule {
+		resp[i] = dto.NewScheduleEntryResponse(&entry)
+	}
+	respondJSON(w, http.StatusOK, resp)
+}
+
+// MyOutstanding returns the outstanding amount of the authenticated customer's loan.
+//
+// @Summary Retrieve my outstanding amount
+// @Description Returns the itemized outstanding amount of the loan owned by the customer identified by the customer scoped bearer token.
+// @Tags Self Service
+// @Produce json
+// @Param If-Modified-Since header string false "Last-Modified value from an earlier response"
+// @Success 200 {object} dto.OutstandingResponse "Outstanding amount"
+// @Header 200 {string} Last-Modified "When the loan or its schedule last changed"
+// @Success 304 "Nothing changed since If-Modified-Since"
+// @Failure 401 {object} dto.ErrorResponse "Missing or invalid customer token"
+// @Failure 403 {object} dto.ErrorResponse "Token is not customer scoped"
+// @Failure 404 {object} dto.ErrorResponse "Customer or loan not found"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /me/outstanding [get]
+// @Security BearerAuth
+func (h *SelfServiceHandler) MyOutstanding(w http.ResponseWriter, r *http.Request) {
+	loanID, err := h.currentLoanID(r.Context())
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	lastModified, err := h.loanService.GetLastModified(r.Context(), loanID)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+	if notModified(w, r, lastModified) {
+		return
+	}
+
+	breakdown, err := h.loanService.GetOutstandingBreakdown(r.Context(), loanID)
+	if err != nil {
+		respondError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, dto.NewOutstandingResponse(breakdown))
+}
@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"billing-engine/internal/api/handler/dto"
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/pkg/scope"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type stubCustomerService struct {
+	customer.CustomerService
+	mock.Mock
+}
+
+func (m *stubCustomerService) GetCustomer(ctx context.Context, customerID int64) (*customer.Customer, error) {
+	args := m.Called(ctx, customerID)
+	if cust, ok := args.Get(0).(*customer.Customer); ok {
+		return cust, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func newScopedRequest(path string, customerID int64) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	return req.WithContext(scope.WithCustomer(req.Context(), customerID))
+}
+
+func TestSelfServiceHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	loanID := int64(7)
+
+	t.Run("lists the loans of the scoped customer", func(t *testing.T) {
+		loanService := new(MockLoanService)
+		customerService := new(stubCustomerService)
+		h := NewSelfServiceHandler(loanService, customerService, logger)
+
+		customerService.On("GetCustomer", mock.Anything, int64(42)).Return(&customer.Customer{CustomerID: 42, LoanID: &loanID}, nil)
+		loanService.On("GetLoan", mock.Anything, loanID, false).Return(&loan.Loan{ID: loanID}, nil)
+
+		rec := httptest.NewRecorder()
+		h.MyLoans(rec, newScopedRequest("/me/loans", 42))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var resp []dto.LoanResponse
+		assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		assert.Len(t, resp, 1)
+		assert.Equal(t, "7", resp[0].ID)
+		loanService.AssertExpectations(t)
+		customerService.AssertExpectations(t)
+	})
+
+	t.Run("returns empty list when customer has no loan", func(t *testing.T) {
+		loanService := new(MockLoanService)
+		customerService := new(stubCustomerService)
+		h := NewSelfServiceHandler(loanService, customerService, logger)
+
+		customerService.On("GetCustomer", mock.Anything, int64(42)).Return(&customer.Customer{CustomerID: 42}, nil)
+
+		rec := httptest.NewRecorder()
+		h.MyLoans(rec, newScopedRequest("/me/loans", 42))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, "[]", rec.Body.String())
+		loanService.AssertNotCalled(t, "GetLoan", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("returns outstanding amount of the scoped customer's loan", func(t *testing.T) {
+		loanService := new(MockLoanService)
+		customerService := new(stubCustomerService)
+		h := NewSelfServiceHandler(loanService, customerService, logger)
+
+		customerService.On("GetCustomer", mock.Anything, int64(42)).Return(&customer.Customer{CustomerID: 42, LoanID: &loanID}, nil)
+		loanService.On("GetLastModified", mock.Anything, loanID).Return(time.Now(), nil)
+		loanService.On("GetOutstandingBreakdown", mock.Anything, loanID).Return(&loan.OutstandingBreakdown{
+			LoanID:       loanID,
+			Installments: 1300.5,
+			Suspense:     50,
+			Total:        1250.5,
+			Items:        []loan.BalanceItem{{Kind: loan.BalanceSuspense, Amount: 50}},
+		}, nil)
+
+		rec := httptest.NewRecorder()
+		h.MyOutstanding(rec, newScopedRequest("/me/outstanding", 42))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var resp dto.OutstandingResponse
+		assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		assert.Equal(t, "1250.50", resp.OutstandingAmount)
+		require.NotNil(t, resp.Breakdown)
+		assert.Equal(t, "1300.50", resp.Breakdown.Installments)
+		assert.Equal(t, "50.00", resp.Breakdown.Suspense)
+		assert.Len(t, resp.Breakdown.Items, 1)
+	})
+
+	t.Run("answers 304 for an unchanged schedule", func(t *testing.T) {
+		loanService := new(MockLoanService)
+		customerService := new(stubCustomerService)
+		h := NewSelfServiceHandler(loanService, customerService, logger)
+		lastModified := time.Date(2025, 3, 1, 10, 30, 15, 500, time.UTC)
+
+		customerService.On("GetCustomer", mock.Anything, int64(42)).Return(&customer.Customer{CustomerID: 42, LoanID: &loanID}, nil)
+		loanService.On("GetLastModified", mock.Anything, loanID).Return(lastModified, nil)
+		loanService.On("GetLoanSchedule", mock.Anything, loanID).Return([]loan.ScheduleEntry{{WeekNumber: 1}}, nil).Once()
+
+		rec := httptest.NewRecorder()
+		h.MySchedule(rec, newScopedRequest("/me/schedule", 42))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "Sat, 01 Mar 2025 10:30:15 GMT", rec.Header().Get("Last-Modified"))
+		assert.Equal(t, "private, no-cache", rec.Header().Get("Cache-Control"))
+
+		req := newScopedRequest("/me/schedule", 42)
+		req.Header.Set("If-Modified-Since", rec.Header().Get("Last-Modified"))
+		rec = httptest.NewRecorder()
+		h.MySchedule(rec, req)
+		assert.Equal(t, http.StatusNotModified, rec.Code)
+		assert.Empty(t, rec.Body.Bytes())
+
+		req = newScopedRequest("/me/schedule", 42)
+		req.Header.Set("If-Modified-Since", "Sat, 01 Mar 2025 10:30:14 GMT")
+		loanService.On("GetLoanSchedule", mock.Anything, loanID).Return([]loan.ScheduleEntry{{WeekNumber: 1}}, nil).Once()
+		rec = httptest.NewRecorder()
+		h.MySchedule(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		loanService.AssertExpectations(t)
+	})
+
+	t.Run("returns not found schedule when customer has no loan", func(t *testing.T) {
+		loanService := new(MockLoanService)
+		customerService := new(stubCustomerService)
+		h := NewSelfServiceHandler(loanService, customerService, logger)
+
+		customerService.On("GetCustomer", mock.Anything, int64(42)).Return(&customer.Customer{CustomerID: 42}, nil)
+
+		rec := httptest.NewRecorder()
+		h.MySchedule(rec, newScopedRequest("/me/schedule", 42))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("returns unauthorized without customer scope", func(t *testing.T) {
+		h := NewSelfServiceHandler(new(MockLoanService), new(stubCustomerService), logger)
+
+		rec := httptest.NewRecorder()
+		h.MySchedule(rec, httptest.NewRequest(http.MethodGet, "/me/schedule", nil))
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+}
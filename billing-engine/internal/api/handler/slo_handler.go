@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"billing-engine/internal/api/handler/dto"
+	"billing-engine/internal/infrastructure/monitoring"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// SLOHandler reports the latency and error budget the SLO middleware
+// tracks, for on-call engineers without access to a metrics backend.
+type SLOHandler struct {
+	tracker *monitoring.SLOTracker
+	logger  *slog.Logger
+}
+
+func NewSLOHandler(t *monitoring.SLOTracker, l *slog.Logger) *SLOHandler {
+	if t == nil {
+		panic("SLO tracker cannot be nil")
+	}
+	if l == nil {
+		panic("logger cannot be nil")
+	}
+	return &SLOHandler{tracker: t, logger: l.With("component", "SLOHandler")}
+}
+
+// GetSummary handles GET /admin/slo
+// @Summary Get API latency and error budget
+// @Description Returns request counts, p95 and p99 latency, error rate and error budget burn over the rolling window, overall and per route. Only 5xx responses spend the budget. The figures are kept in memory and start over when the instance restarts.
+// @Tags Monitoring
+// @Produce json
+// @Success 200 {object} dto.SLOSummaryResponse
+// @Failure 403 {object} dto.ErrorResponse "Token lacks the admin scope"
+// @Router /admin/slo [get]
+// @Security BearerAuth
+func (h *SLOHandler) GetSummary(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, dto.NewSLOSummaryResponse(h.tracker.Summary(time.Now())))
+}
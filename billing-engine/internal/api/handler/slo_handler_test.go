@@ -0,0 +1,54 @@
+package handler_test
+
+import (
+	"billing-engine/internal/api/handler"
+	"billing-engine/internal/api/handler/dto"
+	"billing-engine/internal/infrastructure/monitoring"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSLOHandlerGetSummary(t *testing.T) {
+	tracker := monitoring.NewSLOTracker(time.Hour, 0.99)
+	now := time.Now()
+	for i := range 10 {
+		tracker.Record(http.MethodPost, "/loans/{loanID}/payments", i == 0, 40*time.Millisecond, now)
+	}
+	h := handler.NewSLOHandler(tracker, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	rec := httptest.NewRecorder()
+	h.GetSummary(rec, httptest.NewRequest(http.MethodGet, "/admin/slo", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp dto.SLOSummaryResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.Equal(t, "1h0m0s", resp.Window)
+	assert.Equal(t, 0.99, resp.Objective)
+	assert.Equal(t, uint64(10), resp.Overall.Requests)
+	require.Len(t, resp.Routes, 1)
+	route := resp.Routes[0]
+	assert.Equal(t, "POST", route.Method)
+	assert.Equal(t, "/loans/{loanID}/payments", route.Route)
+	assert.Equal(t, uint64(1), route.Errors)
+	assert.InDelta(t, 0.1, route.ErrorRate, 1e-9)
+	assert.InDelta(t, 10.0, route.BudgetBurnRate, 1e-9)
+	assert.InDelta(t, 48.75, route.P95Ms, 1e-6, "interpolated within the 25ms to 50ms bucket")
+}
+
+func TestSLOHandlerEmptyWindow(t *testing.T) {
+	h := handler.NewSLOHandler(monitoring.NewSLOTracker(0, 0), slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	rec := httptest.NewRecorder()
+	h.GetSummary(rec, httptest.NewRequest(http.MethodGet, "/admin/slo", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"routes":[]`, "routes is an empty list, not null")
+}
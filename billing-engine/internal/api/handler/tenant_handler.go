@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"billing-engine/internal/api/handler/dto"
+	"billing-engine/internal/domain/tenant"
+	"billing-engine/internal/pkg/apperrors"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type TenantHandler struct {
+	service tenant.Service
+	logger  *slog.Logger
+}
+
+func NewTenantHandler(s tenant.Service, l *slog.Logger) *TenantHandler {
+	if s == nil {
+		panic("tenant service cannot be nil")
+	}
+	if l == nil {
+		panic("logger cannot be nil")
+	}
+	return &TenantHandler{service: s, logger: l.With("component", "TenantHandler")}
+}
+
+// GetSettings handles GET /admin/tenants/{tenantID}/settings
+// @Summary Get a tenant's setting overrides
+// @Description Returns the values the tenant overrides: the delinquency thresholds missedPayments and curePayments, the grace period graceDays and the late fee lateFee charged once it is over, the per-client rate limit rateLimitRps and rateLimitBurst, and reminderWindowDays, how long after a loan reached an SMS or email step its reminder is still sent. A missing value means the configured one applies. A token naming a tenant only reaches that tenant's settings.
+// @Tags Admin
+// @Produce json
+// @Param tenantID path string true "Tenant ID, such as default"
+// @Success 200 {object} dto.TenantSettingsResponse
+// @Failure 400 {object} dto.ErrorResponse "Invalid tenant ID"
+// @Failure 403 {object} dto.ErrorResponse "Caller is not an admin, or belongs to another tenant"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /admin/tenants/{tenantID}/settings [get]
+// @Security BearerAuth
+func (h *TenantHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	settings, err := h.service.GetSettings(r.Context(), chi.URLParam(r, "tenantID"))
+	if err != nil {
+		h.logger.WarnContext(r.Context(), "Service failed to get tenant settings", slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, dto.NewTenantSettingsResponse(settings))
+}
+
+// UpdateSettings handles PUT /admin/tenants/{tenantID}/settings
+// @Summary Replace a tenant's setting overrides
+// @Description Replaces every override of the tenant; an omitted value goes back to the configured one. missedPayments, curePayments and rateLimitBurst are at least 1, rateLimitRps is above 0, and graceDays, lateFee and reminderWindowDays are not negative. A lateFee of 0 charges none. The delinquency job, the rate limiter and the reminder escalation of this instance apply the change at once, other instances within CACHE_TENANTSETTINGSTTL. A token naming a tenant only reaches that tenant's settings.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param tenantID path string true "Tenant ID, such as default"
+// @Param request body dto.UpdateTenantSettingsRequest true "New overrides"
+// @Success 200 {object} dto.TenantSettingsResponse "Overrides stored"
+// @Failure 400 {object} dto.ErrorResponse "Invalid tenant ID or value out of range"
+// @Failure 403 {object} dto.ErrorResponse "Caller is not an admin, or belongs to another tenant"
+// @Failure 500 {object} dto.ErrorResponse "Internal server error"
+// @Router /admin/tenants/{tenantID}/settings [put]
+// @Security BearerAuth
+func (h *TenantHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	var req dto.UpdateTenantSettingsRequest
+	if err := decodeJSON(r, &req); err != nil {
+		h.logger.WarnContext(r.Context(), "Failed to decode request body", slog.Any("error", err))
+		respondError(w, fmt.Errorf("%w: %w", apperrors.ErrInvalidArgument, err))
+		return
+	}
+	if err := req.Validate(); err != nil {
+		respondError(w, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err))
+		return
+	}
+
+	settings, err := h.service.UpdateSettings(r.Context(), chi.URLParam(r, "tenantID"), req.Overrides(), actorFromContext(r.Context()))
+	if err != nil {
+		level := slog.LevelWarn
+		if !errors.Is(err, apperrors.ErrForbidden) && !errors.Is(err, apperrors.ErrInvalidArgument) {
+			level = slog.LevelError
+		}
+		h.logger.Log(r.Context(), level, "Service failed to update tenant settings", slog.Any("error", err))
+		respondError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, dto.NewTenantSettingsResponse(settings))
+}
@@ -0,0 +1,131 @@
+package handler_test
+
+import (
+	"billing-engine/internal/api/handler"
+	"billing-engine/internal/api/handler/dto"
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/domain/tenant"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockTenantService struct {
+	mock.Mock
+}
+
+func (m *MockTenantService) GetSettings(ctx context.Context, tenantID string) (*tenant.Settings, error) {
+	args := m.Called(ctx, tenantID)
+	s, _ := args.Get(0).(*tenant.Settings)
+	return s, args.Error(1)
+}
+
+func (m *MockTenantService) UpdateSettings(ctx context.Context, tenantID string, overrides tenant.Overrides, updatedBy string) (*tenant.Settings, error) {
+	args := m.Called(ctx, tenantID, overrides, updatedBy)
+	s, _ := args.Get(0).(*tenant.Settings)
+	return s, args.Error(1)
+}
+
+func (m *MockTenantService) DelinquencyPolicy(ctx context.Context, tenantID string) (loan.DelinquencyPolicy, error) {
+	args := m.Called(ctx, tenantID)
+	return args.Get(0).(loan.DelinquencyPolicy), args.Error(1)
+}
+
+func (m *MockTenantService) RateLimit(ctx context.Context, tenantID string) (float64, int, error) {
+	args := m.Called(ctx, tenantID)
+	return args.Get(0).(float64), args.Int(1), args.Error(2)
+}
+
+func (m *MockTenantService) ReminderWindow(ctx context.Context, tenantID string) (int, error) {
+	args := m.Called(ctx, tenantID)
+	return args.Int(0), args.Error(1)
+}
+
+func TestTenantHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	serve := func(h *handler.TenantHandler, method, path, body string) *httptest.ResponseRecorder {
+		router := chi.NewRouter()
+		router.Get("/admin/tenants/{tenantID}/settings", h.GetSettings)
+		router.Put("/admin/tenants/{tenantID}/settings", h.UpdateSettings)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+
+	t.Run("gets the overrides", func(t *testing.T) {
+		svc := new(MockTenantService)
+		missed := 3
+		updatedAt := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+		svc.On("GetSettings", mock.Anything, tenant.DefaultTenant).Return(&tenant.Settings{
+			TenantID: tenant.DefaultTenant, Overrides: tenant.Overrides{MissedPayments: &missed}, UpdatedBy: "ops", UpdatedAt: updatedAt}, nil)
+
+		rec := serve(handler.NewTenantHandler(svc, logger), http.MethodGet, "/admin/tenants/default/settings", "")
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		var resp dto.TenantSettingsResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, dto.TenantSettingsResponse{TenantID: tenant.DefaultTenant, MissedPayments: &missed, UpdatedBy: "ops", UpdatedAt: &updatedAt}, resp)
+		assert.NotContains(t, rec.Body.String(), "rateLimitRps", "values left to the configuration are omitted")
+	})
+
+	t.Run("replaces the overrides", func(t *testing.T) {
+		svc := new(MockTenantService)
+		burst, rps := 5, 2.5
+		overrides := tenant.Overrides{RateLimitRPS: &rps, RateLimitBurst: &burst}
+		svc.On("UpdateSettings", mock.Anything, tenant.DefaultTenant, overrides, "").
+			Return(&tenant.Settings{TenantID: tenant.DefaultTenant, Overrides: overrides, UpdatedAt: time.Now()}, nil)
+
+		rec := serve(handler.NewTenantHandler(svc, logger), http.MethodPut, "/admin/tenants/default/settings", `{"rateLimitRps":2.5,"rateLimitBurst":5}`)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		var resp dto.TenantSettingsResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, 5, *resp.RateLimitBurst)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("late fee is a decimal amount", func(t *testing.T) {
+		svc := new(MockTenantService)
+		grace, window, fee := 5, 10, 15.5
+		overrides := tenant.Overrides{GraceDays: &grace, LateFee: &fee, ReminderWindowDays: &window}
+		svc.On("UpdateSettings", mock.Anything, "acme", overrides, "").
+			Return(&tenant.Settings{TenantID: "acme", Overrides: overrides, UpdatedAt: time.Now()}, nil)
+		h := handler.NewTenantHandler(svc, logger)
+
+		rec := serve(h, http.MethodPut, "/admin/tenants/acme/settings", `{"graceDays":5,"lateFee":"15.5","reminderWindowDays":10}`)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		var resp dto.TenantSettingsResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, "15.50", *resp.LateFee)
+		assert.Equal(t, 5, *resp.GraceDays)
+		assert.Equal(t, 10, *resp.ReminderWindowDays)
+		assert.Equal(t, http.StatusBadRequest, serve(h, http.MethodPut, "/admin/tenants/acme/settings", `{"lateFee":"ten"}`).Code)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		svc := new(MockTenantService)
+		svc.On("GetSettings", mock.Anything, "acme").Return(nil, fmt.Errorf("%w: the caller belongs to tenant %q, not %q", apperrors.ErrForbidden, "default", "acme"))
+		svc.On("UpdateSettings", mock.Anything, tenant.DefaultTenant, mock.Anything, "").
+			Return(nil, fmt.Errorf("%w: missed payments must be at least 1, got 0", apperrors.ErrInvalidArgument))
+		h := handler.NewTenantHandler(svc, logger)
+
+		assert.Equal(t, http.StatusForbidden, serve(h, http.MethodGet, "/admin/tenants/acme/settings", "").Code)
+		assert.Equal(t, http.StatusBadRequest, serve(h, http.MethodPut, "/admin/tenants/default/settings", `{"missedPayments":0}`).Code)
+		assert.Equal(t, http.StatusBadRequest, serve(h, http.MethodPut, "/admin/tenants/default/settings", `{`).Code)
+	})
+}
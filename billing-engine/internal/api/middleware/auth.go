@@ -2,13 +2,30 @@ package middleware
 
 import (
 	"billing-engine/internal/config"
+	"billing-engine/internal/pkg/i18n"
+	"billing-engine/internal/pkg/scope"
+	"context"
 	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
+const (
+	ScopeCustomer = "customer"
+	// ScopeAdmin is required by the operator routes under /admin.
+	ScopeAdmin = "admin"
+)
+
+type claimsContextKey struct{}
+
+func ClaimsFromContext(ctx context.Context) (jwt.MapClaims, bool) {
+	claims, ok := ctx.Value(claimsContextKey{}).(jwt.MapClaims)
+	return claims, ok
+}
+
 func AuthMiddleware(cfg config.AuthConfig, logger *slog.Logger) func(http.Handler) http.Handler {
 	if !cfg.Enabled {
 		return func(next http.Handler) http.Handler {
@@ -16,10 +33,103 @@ func AuthMiddleware(cfg config.AuthConfig, logger *slog.Logger) func(http.Handle
 		}
 	}
 
+	var keys *jwksCache
+	if cfg.JWKSURL != "" {
+		keys = sharedJWKSCache(cfg.JWKSURL, cfg.JWKSRefreshInterval, logger)
+	}
+	parser := jwt.NewParser(parserOptions(cfg)...)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := validateJWT(r, parser, cfg.JWTSecret, keys, logger)
+			if !ok {
+				writeError(w, r, http.StatusUnauthorized, i18n.MsgUnauthorized)
+				return
+			}
+			tenantID, ok := claimTenant(claims)
+			if !ok {
+				logger.Warn("AuthMiddleware: Invalid tenant claim")
+				writeError(w, r, http.StatusUnauthorized, i18n.MsgUnauthorized)
+				return
+			}
+			if subject, _ := claims.GetSubject(); subject != "" {
+				noteCaller(r.Context(), subject)
+			}
+			ctx := context.WithValue(r.Context(), claimsContextKey{}, claims)
+			next.ServeHTTP(w, r.WithContext(scope.WithTenant(ctx, tenantID)))
+		})
+	}
+}
+
+// AdminCaller reports whether a request carries a valid token with the admin
+// scope, checked as AuthMiddleware checks it. It serves open routes that do
+// more for an admin, such as POST /auth/token issuing another admin token.
+func AdminCaller(cfg config.AuthConfig, logger *slog.Logger) func(*http.Request) bool {
+	var keys *jwksCache
+	if cfg.JWKSURL != "" {
+		keys = sharedJWKSCache(cfg.JWKSURL, cfg.JWKSRefreshInterval, logger)
+	}
+	parser := jwt.NewParser(parserOptions(cfg)...)
+	return func(r *http.Request) bool {
+		claims, ok := validateJWT(r, parser, cfg.JWTSecret, keys, logger)
+		return ok && claimScope(claims) == ScopeAdmin
+	}
+}
+
+// CallerTenant names the tenant of a request's token, checked as
+// AuthMiddleware checks it, for what runs before AuthMiddleware such as the
+// rate limiter. A request without a valid token, or any request when
+// authentication is disabled, belongs to scope.DefaultTenant; AuthMiddleware
+// rejects the invalid ones afterwards, so their failures are not logged here.
+func CallerTenant(cfg config.AuthConfig, logger *slog.Logger) func(*http.Request) string {
+	if !cfg.Enabled {
+		return func(*http.Request) string { return scope.DefaultTenant }
+	}
+	var keys *jwksCache
+	if cfg.JWKSURL != "" {
+		keys = sharedJWKSCache(cfg.JWKSURL, cfg.JWKSRefreshInterval, logger)
+	}
+	parser := jwt.NewParser(parserOptions(cfg)...)
+	quiet := slog.New(slog.DiscardHandler)
+	return func(r *http.Request) string {
+		if r.Header.Get("Authorization") == "" {
+			return scope.DefaultTenant
+		}
+		claims, ok := validateJWT(r, parser, cfg.JWTSecret, keys, quiet)
+		if !ok {
+			return scope.DefaultTenant
+		}
+		tenantID, ok := claimTenant(claims)
+		if !ok {
+			return scope.DefaultTenant
+		}
+		return tenantID
+	}
+}
+
+// StaffOnly rejects tokens issued with the customer self-service scope so they
+// cannot reach the back-office routes.
+func StaffOnly(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if claims, ok := ClaimsFromContext(r.Context()); ok && claimScope(claims) == ScopeCustomer {
+				logger.Warn("AuthMiddleware: Customer scoped token used on staff route", "path", r.URL.Path)
+				writeError(w, r, http.StatusForbidden, i18n.MsgForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// AdminOnly requires a token issued with the admin scope. Like StaffOnly it
+// lets requests through when authentication is disabled and no claims exist.
+func AdminOnly(logger *slog.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if !validateJWT(r, cfg.JWTSecret, logger) {
-				http.Error(w, `{"error":{"message":"Unauthorized"}}`, http.StatusUnauthorized)
+			if claims, ok := ClaimsFromContext(r.Context()); ok && claimScope(claims) != ScopeAdmin {
+				logger.Warn("AuthMiddleware: Token without admin scope used on admin route", "path", r.URL.Path)
+				writeError(w, r, http.StatusForbidden, i18n.MsgForbidden)
 				return
 			}
 			next.ServeHTTP(w, r)
@@ -27,34 +137,134 @@ func AuthMiddleware(cfg config.AuthConfig, logger *slog.Logger) func(http.Handle
 	}
 }
 
-func validateJWT(r *http.Request, secret string, logger *slog.Logger) bool {
+// CustomerScope requires a customer scoped token and injects the token subject
+// as the customer constraint consumed by the domain services.
+func CustomerScope(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := ClaimsFromContext(r.Context())
+			if !ok {
+				logger.Warn("AuthMiddleware: Missing claims for customer scoped route")
+				writeError(w, r, http.StatusUnauthorized, i18n.MsgUnauthorized)
+				return
+			}
+			if claimScope(claims) != ScopeCustomer {
+				logger.Warn("AuthMiddleware: Token is not customer scoped", "path", r.URL.Path)
+				writeError(w, r, http.StatusForbidden, i18n.MsgForbidden)
+				return
+			}
+
+			subject, _ := claims.GetSubject()
+			customerID, err := strconv.ParseInt(subject, 10, 64)
+			if err != nil || customerID <= 0 {
+				logger.Warn("AuthMiddleware: Invalid customer subject", "sub", subject)
+				writeError(w, r, http.StatusUnauthorized, i18n.MsgUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(scope.WithCustomer(r.Context(), customerID)))
+		})
+	}
+}
+
+// TokenFromQuery copies a bearer token passed as a query parameter into the
+// Authorization header. EventSource clients cannot set request headers.
+func TokenFromQuery(param string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token := r.URL.Query().Get(param); token != "" && r.Header.Get("Authorization") == "" {
+				if !strings.HasPrefix(strings.ToLower(token), "bearer ") {
+					token = "Bearer " + token
+				}
+				r.Header.Set("Authorization", token)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// maxTenantIDLength is the size of the tenant_id columns.
+const maxTenantIDLength = 64
+
+// claimTenant is the tenant the token's "tenant" claim names, or
+// scope.DefaultTenant when it has none. A claim that is not a string or
+// does not fit the tenant_id columns is invalid.
+func claimTenant(claims jwt.MapClaims) (string, bool) {
+	raw, present := claims["tenant"]
+	if !present {
+		return scope.DefaultTenant, true
+	}
+	tenantID, _ := raw.(string)
+	tenantID = strings.TrimSpace(tenantID)
+	if tenantID == "" || len(tenantID) > maxTenantIDLength {
+		return "", false
+	}
+	return tenantID, true
+}
+
+func claimScope(claims jwt.MapClaims) string {
+	s, _ := claims["scope"].(string)
+	return s
+}
+
+// parserOptions turns the auth config into claim checks. exp and nbf are
+// always checked when present; iss and aud only when configured.
+func parserOptions(cfg config.AuthConfig) []jwt.ParserOption {
+	var methods []string
+	if cfg.JWTSecret != "" || cfg.JWKSURL == "" {
+		methods = append(methods, "HS256", "HS384", "HS512")
+	}
+	if cfg.JWKSURL != "" {
+		methods = append(methods, "RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512")
+	}
+
+	opts := []jwt.ParserOption{jwt.WithValidMethods(methods), jwt.WithLeeway(cfg.Leeway)}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+	if cfg.RequireExpiry {
+		opts = append(opts, jwt.WithExpirationRequired())
+	}
+	return opts
+}
+
+func validateJWT(r *http.Request, parser *jwt.Parser, secret string, keys *jwksCache, logger *slog.Logger) (jwt.MapClaims, bool) {
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
 		logger.Warn("AuthMiddleware: Missing Authorization header")
-		return false
+		return nil, false
 	}
 
 	parts := strings.SplitN(authHeader, " ", 2)
 	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
 		logger.Warn("AuthMiddleware: Invalid Authorization header format")
-		return false
+		return nil, false
 	}
 	tokenString := parts[1]
 
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			logger.Warn("AuthMiddleware: Unexpected signing method")
-			return nil, http.ErrAbortHandler
+	claims := jwt.MapClaims{}
+	token, err := parser.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		switch token.Method.(type) {
+		case *jwt.SigningMethodHMAC:
+			return []byte(secret), nil
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS, *jwt.SigningMethodECDSA:
+			if keys != nil {
+				kid, _ := token.Header["kid"].(string)
+				return keys.Key(r.Context(), kid)
+			}
 		}
-		return []byte(secret), nil
+		logger.Warn("AuthMiddleware: Unexpected signing method")
+		return nil, http.ErrAbortHandler
 	})
 
 	if err != nil || !token.Valid {
 		logger.Warn("AuthMiddleware: Invalid token", "error", err)
-		return false
+		return nil, false
 	}
 
 	logger.Info("AuthMiddleware: Authenticated request", "token", tokenString)
-	return true
+	return claims, true
 }
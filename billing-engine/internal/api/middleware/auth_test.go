@@ -2,18 +2,21 @@ package middleware
 
 import (
 	"billing-engine/internal/config"
+	"billing-engine/internal/pkg/scope"
 	"bytes"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func TestAuthMiddleware(t *testing.T) {
-	const statusErrorMsg = "expected status %d, got %d"
+const statusErrorMsg = "expected status %d, got %d"
 
+func TestAuthMiddleware(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
 	secret := "testsecret"
 
@@ -100,3 +103,297 @@ func TestAuthMiddleware(t *testing.T) {
 		}
 	})
 }
+
+func signTestToken(t *testing.T, secret string, claims jwt.MapClaims) string {
+	t.Helper()
+	tokenString, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return tokenString
+}
+
+func TestCustomerScopeMiddleware(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	secret := "testsecret"
+	cfg := config.AuthConfig{Enabled: true, JWTSecret: secret}
+
+	var gotCustomerID int64
+	var gotScoped bool
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotCustomerID, gotScoped = scope.CustomerFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})
+	chain := AuthMiddleware(cfg, logger)(CustomerScope(logger)(nextHandler))
+
+	t.Run("injects customer constraint for customer scoped token", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/me/loans", nil)
+		req.Header.Set("Authorization", "Bearer "+signTestToken(t, secret, jwt.MapClaims{"sub": "42", "scope": ScopeCustomer}))
+		rec := httptest.NewRecorder()
+
+		chain.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+		if !gotScoped || gotCustomerID != 42 {
+			t.Errorf("expected customer scope 42, got %d (scoped=%v)", gotCustomerID, gotScoped)
+		}
+	})
+
+	t.Run("rejects staff token on customer route", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/me/loans", nil)
+		req.Header.Set("Authorization", "Bearer "+signTestToken(t, secret, jwt.MapClaims{"username": "ops"}))
+		rec := httptest.NewRecorder()
+
+		chain.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("expected status %d, got %d", http.StatusForbidden, rec.Code)
+		}
+	})
+
+	t.Run("rejects customer token with non numeric subject", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/me/loans", nil)
+		req.Header.Set("Authorization", "Bearer "+signTestToken(t, secret, jwt.MapClaims{"sub": "abc", "scope": ScopeCustomer}))
+		rec := httptest.NewRecorder()
+
+		chain.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected status %d, got %d", http.StatusUnauthorized, rec.Code)
+		}
+	})
+}
+
+func TestAuthMiddlewareTenant(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	secret := "testsecret"
+	cfg := config.AuthConfig{Enabled: true, JWTSecret: secret}
+
+	var gotTenant string
+	var gotScoped bool
+	chain := AuthMiddleware(cfg, logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotTenant, gotScoped = scope.TenantFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for name, tc := range map[string]struct {
+		claims jwt.MapClaims
+		status int
+		tenant string
+	}{
+		"tenant claim":    {jwt.MapClaims{"sub": "ops", "tenant": "acme"}, http.StatusOK, "acme"},
+		"no tenant claim": {jwt.MapClaims{"sub": "ops"}, http.StatusOK, scope.DefaultTenant},
+		"empty tenant":    {jwt.MapClaims{"sub": "ops", "tenant": " "}, http.StatusUnauthorized, ""},
+		"numeric tenant":  {jwt.MapClaims{"sub": "ops", "tenant": 7}, http.StatusUnauthorized, ""},
+		"tenant too long": {jwt.MapClaims{"sub": "ops", "tenant": strings.Repeat("a", 65)}, http.StatusUnauthorized, ""},
+	} {
+		t.Run(name, func(t *testing.T) {
+			gotTenant, gotScoped = "", false
+			req := httptest.NewRequest(http.MethodGet, "/loans/1", nil)
+			req.Header.Set("Authorization", "Bearer "+signTestToken(t, secret, tc.claims))
+			rec := httptest.NewRecorder()
+
+			chain.ServeHTTP(rec, req)
+
+			if rec.Code != tc.status {
+				t.Fatalf("expected status %d, got %d", tc.status, rec.Code)
+			}
+			if tc.status == http.StatusOK && (!gotScoped || gotTenant != tc.tenant) {
+				t.Errorf("expected tenant %q, got %q (scoped=%v)", tc.tenant, gotTenant, gotScoped)
+			}
+		})
+	}
+}
+
+func TestCallerTenant(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	secret := "testsecret"
+	tenantOf := CallerTenant(config.AuthConfig{Enabled: true, JWTSecret: secret}, logger)
+
+	for name, tc := range map[string]struct {
+		authorization string
+		tenant        string
+	}{
+		"tenant claim":    {"Bearer " + signTestToken(t, secret, jwt.MapClaims{"sub": "ops", "tenant": "acme"}), "acme"},
+		"no tenant claim": {"Bearer " + signTestToken(t, secret, jwt.MapClaims{"sub": "ops"}), scope.DefaultTenant},
+		"invalid tenant":  {"Bearer " + signTestToken(t, secret, jwt.MapClaims{"sub": "ops", "tenant": 7}), scope.DefaultTenant},
+		"wrong secret":    {"Bearer " + signTestToken(t, "other", jwt.MapClaims{"sub": "ops", "tenant": "acme"}), scope.DefaultTenant},
+		"no token":        {"", scope.DefaultTenant},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/loans/1", nil)
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+			if got := tenantOf(req); got != tc.tenant {
+				t.Errorf("expected tenant %q, got %q", tc.tenant, got)
+			}
+		})
+	}
+
+	t.Run("authentication disabled", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/loans/1", nil)
+		req.Header.Set("Authorization", "Bearer "+signTestToken(t, secret, jwt.MapClaims{"sub": "ops", "tenant": "acme"}))
+		if got := CallerTenant(config.AuthConfig{JWTSecret: secret}, logger)(req); got != scope.DefaultTenant {
+			t.Errorf("expected tenant %q, got %q", scope.DefaultTenant, got)
+		}
+	})
+}
+
+func TestStaffOnlyMiddleware(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	secret := "testsecret"
+	cfg := config.AuthConfig{Enabled: true, JWTSecret: secret}
+
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	chain := AuthMiddleware(cfg, logger)(StaffOnly(logger)(nextHandler))
+
+	t.Run("rejects customer scoped token", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/loans/1", nil)
+		req.Header.Set("Authorization", "Bearer "+signTestToken(t, secret, jwt.MapClaims{"sub": "42", "scope": ScopeCustomer}))
+		rec := httptest.NewRecorder()
+
+		chain.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("expected status %d, got %d", http.StatusForbidden, rec.Code)
+		}
+	})
+
+	t.Run("allows staff token", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/loans/1", nil)
+		req.Header.Set("Authorization", "Bearer "+signTestToken(t, secret, jwt.MapClaims{"username": "ops"}))
+		rec := httptest.NewRecorder()
+
+		chain.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+	})
+}
+
+func TestAdminOnlyMiddleware(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	secret := "testsecret"
+	cfg := config.AuthConfig{Enabled: true, JWTSecret: secret}
+
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	chain := AuthMiddleware(cfg, logger)(AdminOnly(logger)(nextHandler))
+
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+		want   int
+	}{
+		{"allows admin token", jwt.MapClaims{"username": "ops", "scope": ScopeAdmin}, http.StatusOK},
+		{"rejects staff token", jwt.MapClaims{"username": "ops"}, http.StatusForbidden},
+		{"rejects customer scoped token", jwt.MapClaims{"sub": "42", "scope": ScopeCustomer}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/admin/sandbox/clock/advance", nil)
+			req.Header.Set("Authorization", "Bearer "+signTestToken(t, secret, tt.claims))
+			rec := httptest.NewRecorder()
+
+			chain.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("expected status %d, got %d", tt.want, rec.Code)
+			}
+		})
+	}
+}
+
+func TestTokenFromQuery(t *testing.T) {
+	var got string
+	handler := TokenFromQuery("access_token")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("Authorization")
+	}))
+
+	t.Run("copies raw token into authorization header", func(t *testing.T) {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/events/stream?access_token=abc", nil))
+		if got != "Bearer abc" {
+			t.Errorf("expected %q, got %q", "Bearer abc", got)
+		}
+	})
+
+	t.Run("keeps existing authorization header", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/events/stream?access_token=abc", nil)
+		req.Header.Set("Authorization", "Bearer header")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		if got != "Bearer header" {
+			t.Errorf("expected %q, got %q", "Bearer header", got)
+		}
+	})
+}
+
+func TestAuthMiddlewareClaimValidation(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	secret := "testsecret"
+	cfg := config.AuthConfig{
+		Enabled:       true,
+		JWTSecret:     secret,
+		Issuer:        "https://id.example.com",
+		Audience:      "billing-engine",
+		RequireExpiry: true,
+		Leeway:        5 * time.Second,
+	}
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	chain := AuthMiddleware(cfg, logger)(nextHandler)
+
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"username": "ops",
+			"iss":      "https://id.example.com",
+			"aud":      "billing-engine",
+			"exp":      time.Now().Add(time.Hour).Unix(),
+		}
+	}
+	with := func(key string, value any) jwt.MapClaims {
+		claims := valid()
+		if value == nil {
+			delete(claims, key)
+		} else {
+			claims[key] = value
+		}
+		return claims
+	}
+
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+		want   int
+	}{
+		{"valid claims", valid(), http.StatusOK},
+		{"audience list containing ours", with("aud", []string{"other", "billing-engine"}), http.StatusOK},
+		{"expired within leeway", with("exp", time.Now().Add(-2*time.Second).Unix()), http.StatusOK},
+		{"wrong issuer", with("iss", "https://evil.example.com"), http.StatusUnauthorized},
+		{"missing issuer", with("iss", nil), http.StatusUnauthorized},
+		{"wrong audience", with("aud", "notify-service"), http.StatusUnauthorized},
+		{"expired", with("exp", time.Now().Add(-time.Minute).Unix()), http.StatusUnauthorized},
+		{"missing expiry", with("exp", nil), http.StatusUnauthorized},
+		{"not valid yet", with("nbf", time.Now().Add(time.Minute).Unix()), http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/loans/1", nil)
+			req.Header.Set("Authorization", "Bearer "+signTestToken(t, secret, tt.claims))
+			rec := httptest.NewRecorder()
+
+			chain.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf(statusErrorMsg, tt.want, rec.Code)
+			}
+		})
+	}
+}
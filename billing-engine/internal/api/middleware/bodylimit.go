@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"billing-engine/internal/config"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// BodyLimitMiddleware caps how much of a request body handlers can read. The
+// limit depends on the matched route, which chi only knows once routing is
+// done, so the body is wrapped here and the limit is looked up on the first
+// read. Reading past the limit fails with *http.MaxBytesError.
+type BodyLimitMiddleware struct {
+	defaultLimit int64
+	routes       map[string]int64
+	logger       *slog.Logger
+}
+
+// NewBodyLimitMiddleware applies cfg.DefaultBytes to every route except
+// uploads, which are listed as "METHOD pattern" and left to the handler's
+// own limit. Entries in cfg.Routes override both. Patterns are given without
+// the API version and apply to the route in every version.
+func NewBodyLimitMiddleware(cfg config.BodyLimitConfig, uploads []string, logger *slog.Logger) *BodyLimitMiddleware {
+	routes := make(map[string]int64, len(uploads)+len(cfg.Routes))
+	for _, route := range uploads {
+		routes[routeKey(route)] = 0
+	}
+	for route, limit := range cfg.Routes {
+		routes[routeKey(route)] = limit
+	}
+	return &BodyLimitMiddleware{
+		defaultLimit: cfg.DefaultBytes,
+		routes:       routes,
+		logger:       logger,
+	}
+}
+
+// routeKey normalises "POST /loans/{loanID}/payments/" and the lower-cased
+// keys viper produces to the same form.
+func routeKey(route string) string {
+	method, pattern, _ := strings.Cut(strings.TrimSpace(route), " ")
+	pattern = strings.TrimSpace(pattern)
+	if len(pattern) > 1 {
+		pattern = strings.TrimSuffix(pattern, "/")
+	}
+	return strings.ToUpper(method) + " " + strings.ToLower(pattern)
+}
+
+func (m *BodyLimitMiddleware) limitFor(r *http.Request) int64 {
+	pattern := r.URL.Path
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+		pattern = UnversionedPattern(rctx.RoutePattern())
+	}
+	if limit, ok := m.routes[routeKey(r.Method+" "+pattern)]; ok {
+		return limit
+	}
+	return m.defaultLimit
+}
+
+func (m *BodyLimitMiddleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = &limitedBody{m: m, w: w, r: r, body: r.Body}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+type limitedBody struct {
+	m      *BodyLimitMiddleware
+	w      http.ResponseWriter
+	r      *http.Request
+	body   io.ReadCloser
+	reader io.Reader
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.reader == nil {
+		limit := b.m.limitFor(b.r)
+		switch {
+		case limit <= 0:
+			b.reader = b.body
+		case b.r.ContentLength > limit:
+			// Refuse before reading anything when the client announced a
+			// body that cannot fit.
+			b.m.logger.WarnContext(b.r.Context(), "Request body exceeds limit",
+				"path", b.r.URL.Path, "content_length", b.r.ContentLength, "limit", limit)
+			return 0, &http.MaxBytesError{Limit: limit}
+		default:
+			b.reader = http.MaxBytesReader(b.w, b.body, limit)
+		}
+	}
+	return b.reader.Read(p)
+}
+
+func (b *limitedBody) Close() error {
+	return b.body.Close()
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"billing-engine/internal/config"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestBodyLimitMiddleware(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	limits := NewBodyLimitMiddleware(config.BodyLimitConfig{
+		DefaultBytes: 64,
+		// viper hands map keys over lower-cased.
+		Routes: map[string]int64{"post /loans/{loanid}/payments": 8},
+	}, []string{"POST /customers/import"}, logger)
+
+	readBody := func(w http.ResponseWriter, r *http.Request) {
+		_, err := io.ReadAll(r.Body)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	router := chi.NewRouter()
+	router.Use(limits.Middleware)
+	router.Route("/loans", func(r chi.Router) {
+		r.Post("/", readBody)
+		r.Post("/{loanID}/payments", readBody)
+	})
+	router.Post("/customers/import", readBody)
+
+	tests := []struct {
+		name    string
+		path    string
+		body    string
+		chunked bool
+		want    int
+	}{
+		{"within default limit", "/loans/", strings.Repeat("a", 64), false, http.StatusOK},
+		{"over default limit", "/loans/", strings.Repeat("a", 65), false, http.StatusRequestEntityTooLarge},
+		{"over default limit without Content-Length", "/loans/", strings.Repeat("a", 65), true, http.StatusRequestEntityTooLarge},
+		{"route override", "/loans/42/payments", `{"amount":"1"}`, false, http.StatusRequestEntityTooLarge},
+		{"route override without Content-Length", "/loans/42/payments", `{"amount":"1"}`, true, http.StatusRequestEntityTooLarge},
+		{"route override within limit", "/loans/42/payments", "{}", false, http.StatusOK},
+		{"upload route is exempt", "/customers/import", strings.Repeat("a", 1024), false, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body io.Reader = strings.NewReader(tt.body)
+			if tt.chunked {
+				// Hide the length so only the reader limit can catch it.
+				body = io.MultiReader(body)
+			}
+			req := httptest.NewRequest(http.MethodPost, tt.path, body)
+			if tt.chunked {
+				req.ContentLength = -1
+			}
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf(expectedStatus, tt.want, rec.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"billing-engine/internal/config"
+	"billing-engine/internal/infrastructure/monitoring"
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type callerKey struct{}
+
+// caller is filled in by AuthMiddleware further in, so that a deprecated
+// call is counted under the token's subject rather than the client's IP.
+type caller struct {
+	subject string
+}
+
+// DeprecationMiddleware tags the responses of deprecated routes with
+// Deprecation, and Sunset once the day they go away is set, and counts the
+// calls to them per client on a DeprecationTracker.
+type DeprecationMiddleware struct {
+	routes       map[string]time.Time
+	legacySunset time.Time
+	tracker      *monitoring.DeprecationTracker
+
+	walk  sync.Once
+	known map[string]bool
+}
+
+// NewDeprecationMiddleware deprecates the routes in cfg.DeprecatedRoutes and,
+// through LegacyRoutes, the unprefixed ones.
+func NewDeprecationMiddleware(cfg config.APIConfig, tracker *monitoring.DeprecationTracker) *DeprecationMiddleware {
+	routes := make(map[string]time.Time, len(cfg.DeprecatedRoutes))
+	for route, sunset := range cfg.RouteSunsets() {
+		routes[routeKey(route)] = sunset
+	}
+	return &DeprecationMiddleware{routes: routes, legacySunset: cfg.Sunset(), tracker: tracker}
+}
+
+// Middleware tags the calls to the routes deprecated in every version. It
+// goes in front of a version's routes.
+func (m *DeprecationMiddleware) Middleware(next http.Handler) http.Handler {
+	if len(m.routes) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := m.findRoute(r)
+		sunset, ok := m.routes[routeKey(r.Method+" "+UnversionedPattern(route))]
+		if route == "" || !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		m.serve(next, w, r, route, sunset)
+	})
+}
+
+// LegacyRoutes marks requests to the unprefixed routes, which version still
+// serves, and tags every one of them as deprecated. Responses also carry a
+// Link to the same path below the version's prefix as the successor. The
+// Sunset is the legacy routes' unless the route itself goes away sooner.
+func (m *DeprecationMiddleware) LegacyRoutes(version string) func(http.Handler) http.Handler {
+	mark := withAPIVersion(apiVersion{name: version})
+	successor := "/" + version
+	return func(next http.Handler) http.Handler {
+		next = mark(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Link", "<"+successor+r.URL.Path+`>; rel="successor-version"`)
+			route := m.findRoute(r)
+			sunset := m.legacySunset
+			if own, ok := m.routes[routeKey(r.Method+" "+route)]; ok && !own.IsZero() && (sunset.IsZero() || own.Before(sunset)) {
+				sunset = own
+			}
+			m.serve(next, w, r, route, sunset)
+		})
+	}
+}
+
+// findRoute is the pattern of the route r is about to reach, or "" when it
+// matches none. The headers have to be set before the route runs, which is
+// before chi has resolved the pattern into the request's route context.
+func (m *DeprecationMiddleware) findRoute(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil || rctx.Routes == nil {
+		return ""
+	}
+	path := r.URL.RawPath
+	if path == "" {
+		path = r.URL.Path
+	}
+	route := trimRoute(rctx.Routes.Find(chi.NewRouteContext(), r.Method, path))
+	// Find also answers with the path of a route group, where routing ends
+	// in the group's 404, so the pattern has to be one the router serves.
+	if !m.endpoints(rctx.Routes)[r.Method+" "+route] {
+		return ""
+	}
+	return route
+}
+
+// endpoints lists the routes the router serves as "METHOD pattern".
+func (m *DeprecationMiddleware) endpoints(routes chi.Routes) map[string]bool {
+	m.walk.Do(func() {
+		m.known = map[string]bool{}
+		_ = chi.Walk(routes, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+			m.known[method+" "+trimRoute(strings.ReplaceAll(route, "/*/", "/"))] = true
+			return nil
+		})
+	})
+	return m.known
+}
+
+func trimRoute(route string) string {
+	if len(route) > 1 {
+		return strings.TrimSuffix(route, "/")
+	}
+	return route
+}
+
+// serve tags the response as deprecated and counts the call to route under
+// the token's subject, or the client's IP when it sent no valid token.
+// Paths that match no route are not counted, so that scanners cannot fill
+// the tracker.
+func (m *DeprecationMiddleware) serve(next http.Handler, w http.ResponseWriter, r *http.Request, route string, sunset time.Time) {
+	w.Header().Set("Deprecation", "true")
+	if !sunset.IsZero() {
+		w.Header().Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+	}
+	c := &caller{}
+	next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), callerKey{}, c)))
+
+	if route == "" {
+		return
+	}
+	client := c.subject
+	if client == "" {
+		client = clientIP(r)
+	}
+	m.tracker.Record(r.Method, route, client, time.Now())
+}
+
+// noteCaller names the subject of the token a deprecated call was made with.
+func noteCaller(ctx context.Context, subject string) {
+	if c, ok := ctx.Value(callerKey{}).(*caller); ok {
+		c.subject = subject
+	}
+}
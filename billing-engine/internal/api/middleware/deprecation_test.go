@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"billing-engine/internal/config"
+	"billing-engine/internal/infrastructure/monitoring"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeprecationMiddleware(t *testing.T) {
+	const secret = "deprecation-secret"
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	tracker := monitoring.NewDeprecationTracker()
+	deprecations := NewDeprecationMiddleware(config.APIConfig{
+		LegacySunset:     "2026-06-30",
+		DeprecatedRoutes: map[string]string{"get /loans/{loanid}/history": "2026-03-31", "GET /loans/{loanID}/fees": ""},
+	}, tracker)
+
+	// Mounted the way the API router mounts its versions.
+	v1 := chi.NewRouter()
+	v1.Route("/loans", func(r chi.Router) {
+		r.Use(AuthMiddleware(config.AuthConfig{Enabled: true, JWTSecret: secret}, logger))
+		ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+		r.Get("/{loanID}", ok)
+		r.Get("/{loanID}/fees", ok)
+		r.Get("/{loanID}/history", ok)
+	})
+	router := chi.NewRouter()
+	router.With(APIVersion("v1"), deprecations.Middleware).Mount("/v1", v1)
+	router.With(deprecations.LegacyRoutes("v1")).Mount("/", v1)
+
+	token := "Bearer " + signTestToken(t, secret, jwt.MapClaims{"sub": "collections-batch"})
+	serve := func(path, authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = "203.0.113.7:41000"
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("current routes", func(t *testing.T) {
+		rec := serve("/v1/loans/3", token)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.NotContains(t, rec.Header(), "Deprecation")
+	})
+
+	t.Run("deprecated routes", func(t *testing.T) {
+		rec := serve("/v1/loans/3/history", token)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "true", rec.Header().Get("Deprecation"))
+		assert.Equal(t, "Tue, 31 Mar 2026 00:00:00 GMT", rec.Header().Get("Sunset"))
+		assert.NotContains(t, rec.Header(), "Link")
+
+		rec = serve("/v1/loans/3/fees", token)
+		assert.Equal(t, "true", rec.Header().Get("Deprecation"))
+		assert.NotContains(t, rec.Header(), "Sunset", "no date has been set")
+	})
+
+	t.Run("legacy routes", func(t *testing.T) {
+		rec := serve("/loans/3", "")
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Equal(t, "v1", rec.Header().Get(APIVersionHeader))
+		assert.Equal(t, "true", rec.Header().Get("Deprecation"))
+		assert.Equal(t, `</v1/loans/3>; rel="successor-version"`, rec.Header().Get("Link"))
+		assert.Equal(t, "Tue, 30 Jun 2026 00:00:00 GMT", rec.Header().Get("Sunset"))
+
+		rec = serve("/loans/3/history", token)
+		assert.Equal(t, "Tue, 31 Mar 2026 00:00:00 GMT", rec.Header().Get("Sunset"), "the route goes away before the legacy routes")
+
+		rec = serve("/loans", token)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	usage := tracker.Usage(10)
+	require.Len(t, usage, 4, "paths that match no route are not counted")
+	calls := map[string]uint64{}
+	for _, u := range usage {
+		calls[u.Method+" "+u.Route+" "+u.Client] = u.Requests
+	}
+	assert.Equal(t, map[string]uint64{
+		"GET /v1/loans/{loanID}/history collections-batch": 1,
+		"GET /v1/loans/{loanID}/fees collections-batch":    1,
+		"GET /loans/{loanID} 203.0.113.7":                  1,
+		"GET /loans/{loanID}/history collections-batch":    1,
+	}, calls, "calls with a token count under its subject, others under the IP")
+}
+
+func TestDeprecationMiddlewareWithoutRoutes(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	handler := NewDeprecationMiddleware(config.APIConfig{}, monitoring.NewDeprecationTracker()).Middleware(next)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/loans/3", nil))
+
+	assert.NotContains(t, rec.Header(), "Deprecation")
+}
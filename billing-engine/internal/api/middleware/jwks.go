@@ -0,0 +1,218 @@
+package middleware
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultJWKSRefreshInterval = time.Hour
+
+	// jwksMinRefetchInterval limits how often an unknown key ID can trigger a
+	// fetch, so tokens with made up key IDs cannot hammer the provider.
+	jwksMinRefetchInterval = time.Minute
+
+	jwksFetchTimeout = 10 * time.Second
+)
+
+var errUnknownKeyID = errors.New("no signing key with this key ID")
+
+// jwksCache holds the signing keys published by an identity provider. Keys
+// are fetched on first use and again once refreshInterval has passed or when
+// a token names a key ID the cache does not know, which is how rotated keys
+// are picked up. If a fetch fails, the keys fetched before keep being used.
+type jwksCache struct {
+	url             string
+	refreshInterval time.Duration
+	client          *http.Client
+	logger          *slog.Logger
+	now             func() time.Time
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+var (
+	jwksCachesMu sync.Mutex
+	jwksCaches   = map[string]*jwksCache{}
+)
+
+// sharedJWKSCache returns the cache for url. The router builds one auth
+// middleware per route group, and they should all share a single copy of
+// the provider's keys.
+func sharedJWKSCache(url string, refreshInterval time.Duration, logger *slog.Logger) *jwksCache {
+	jwksCachesMu.Lock()
+	defer jwksCachesMu.Unlock()
+	if c, ok := jwksCaches[url]; ok {
+		return c
+	}
+	c := newJWKSCache(url, refreshInterval, logger)
+	jwksCaches[url] = c
+	return c
+}
+
+func newJWKSCache(url string, refreshInterval time.Duration, logger *slog.Logger) *jwksCache {
+	if refreshInterval <= 0 {
+		refreshInterval = defaultJWKSRefreshInterval
+	}
+	return &jwksCache{
+		url:             url,
+		refreshInterval: refreshInterval,
+		client:          &http.Client{Timeout: jwksFetchTimeout},
+		logger:          logger.With("component", "JWKSCache"),
+		now:             time.Now,
+	}
+}
+
+// Key returns the public key published under kid.
+func (c *jwksCache) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	stale := c.keys == nil || now.Sub(c.fetchedAt) >= c.refreshInterval
+	if _, known := c.keys[kid]; !known && now.Sub(c.fetchedAt) >= jwksMinRefetchInterval {
+		stale = true
+	}
+	if stale {
+		if err := c.refresh(ctx); err != nil {
+			c.logger.WarnContext(ctx, "Failed to fetch JWKS, using cached keys", slog.String("url", c.url), slog.Any("error", err))
+			if c.keys == nil {
+				return nil, err
+			}
+		}
+	}
+
+	key, ok := c.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", errUnknownKeyID, kid)
+	}
+	return key, nil
+}
+
+// refresh must be called with c.mu held. fetchedAt moves on failures too, so
+// an unreachable provider is not retried on every request.
+func (c *jwksCache) refresh(ctx context.Context) error {
+	c.fetchedAt = c.now()
+
+	ctx, cancel := context.WithTimeout(ctx, jwksFetchTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return fmt.Errorf("build JWKS request: %w", err)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetch JWKS: unexpected status %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			c.logger.WarnContext(ctx, "Skipping unusable JWKS key", slog.String("kid", jwk.Kid), slog.Any("error", err))
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	if len(keys) == 0 {
+		return errors.New("JWKS contains no usable signing keys")
+	}
+
+	c.keys = keys
+	c.logger.InfoContext(ctx, "Fetched JWKS", slog.String("url", c.url), slog.Int("keys", len(keys)))
+	return nil
+}
+
+// jsonWebKey is the subset of RFC 7517 needed for RSA and EC signing keys.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64URLInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("modulus: %w", err)
+		}
+		e, err := base64URLInt(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("exponent: %w", err)
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("exponent too large")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64URLInt(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("x coordinate: %w", err)
+		}
+		y, err := base64URLInt(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("y coordinate: %w", err)
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+func base64URLInt(s string) (*big.Int, error) {
+	if s == "" {
+		return nil, errors.New("missing value")
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
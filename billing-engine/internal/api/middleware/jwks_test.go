@@ -0,0 +1,173 @@
+package middleware
+
+import (
+	"billing-engine/internal/config"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwksServer serves whatever key set it currently holds, so tests can rotate
+// keys or make the provider fail.
+type jwksServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	keys    []map[string]string
+	status  int
+	fetches int
+}
+
+func newJWKSServer(t *testing.T) *jwksServer {
+	t.Helper()
+	s := &jwksServer{status: http.StatusOK}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.fetches++
+		if s.status != http.StatusOK {
+			w.WriteHeader(s.status)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": s.keys})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) serve(status int, keys ...map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = status
+	s.keys = keys
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func rsaJWK(kid string, key *rsa.PrivateKey) map[string]string {
+	return map[string]string{
+		"kty": "RSA", "kid": kid, "use": "sig", "alg": "RS256",
+		"n": b64(key.N.Bytes()), "e": b64(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func ecJWK(kid string, key *ecdsa.PrivateKey) map[string]string {
+	return map[string]string{
+		"kty": "EC", "kid": kid, "crv": "P-256",
+		"x": b64(key.X.FillBytes(make([]byte, 32))), "y": b64(key.Y.FillBytes(make([]byte, 32))),
+	}
+}
+
+func signWithKey(t *testing.T, method jwt.SigningMethod, kid string, key any) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, jwt.MapClaims{"username": "ops", "exp": time.Now().Add(time.Hour).Unix()})
+	token.Header["kid"] = kid
+	tokenString, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return tokenString
+}
+
+func mustRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+	return key
+}
+
+func TestAuthMiddlewareJWKS(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	rsaKey := mustRSAKey(t)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate EC key: %v", err)
+	}
+
+	server := newJWKSServer(t)
+	server.serve(http.StatusOK, rsaJWK("rsa-1", rsaKey), ecJWK("ec-1", ecKey))
+	chain := AuthMiddleware(config.AuthConfig{Enabled: true, JWKSURL: server.URL}, logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"RS256 token", signWithKey(t, jwt.SigningMethodRS256, "rsa-1", rsaKey), http.StatusOK},
+		{"ES256 token", signWithKey(t, jwt.SigningMethodES256, "ec-1", ecKey), http.StatusOK},
+		{"unknown key ID", signWithKey(t, jwt.SigningMethodRS256, "rsa-2", rsaKey), http.StatusUnauthorized},
+		{"key ID of another key", signWithKey(t, jwt.SigningMethodRS256, "rsa-1", mustRSAKey(t)), http.StatusUnauthorized},
+		{"HMAC token without shared secret", signTestToken(t, "guessed-secret", jwt.MapClaims{"username": "ops"}), http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/loans/1", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rec := httptest.NewRecorder()
+
+			chain.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf(statusErrorMsg, tt.want, rec.Code)
+			}
+		})
+	}
+}
+
+func TestJWKSCacheRotation(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	oldKey, newKey := mustRSAKey(t), mustRSAKey(t)
+
+	server := newJWKSServer(t)
+	server.serve(http.StatusOK, rsaJWK("old", oldKey))
+
+	now := time.Date(2025, 4, 1, 12, 0, 0, 0, time.UTC)
+	cache := newJWKSCache(server.URL, time.Hour, logger)
+	cache.now = func() time.Time { return now }
+
+	if _, err := cache.Key(ctx, "old"); err != nil {
+		t.Fatalf("expected old key, got %v", err)
+	}
+	if _, err := cache.Key(ctx, "old"); err != nil || server.fetches != 1 {
+		t.Fatalf("expected cached key without refetch, got err=%v fetches=%d", err, server.fetches)
+	}
+
+	server.serve(http.StatusOK, rsaJWK("new", newKey))
+	if _, err := cache.Key(ctx, "new"); err == nil {
+		t.Fatal("expected unknown key ID to be rejected right after a fetch")
+	}
+
+	now = now.Add(2 * jwksMinRefetchInterval)
+	if _, err := cache.Key(ctx, "new"); err != nil {
+		t.Fatalf("expected rotated key after refetch, got %v", err)
+	}
+	if _, err := cache.Key(ctx, "old"); err == nil {
+		t.Fatal("expected retired key to be gone")
+	}
+
+	server.serve(http.StatusInternalServerError)
+	now = now.Add(2 * time.Hour)
+	if _, err := cache.Key(ctx, "new"); err != nil {
+		t.Fatalf("expected cached key while provider fails, got %v", err)
+	}
+}
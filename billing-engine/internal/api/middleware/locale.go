@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"billing-engine/internal/pkg/i18n"
+	"encoding/json"
+	"net/http"
+)
+
+// Locale negotiates the language of error messages from Accept-Language. The
+// locale goes into the request context and into the Content-Language
+// response header, which is where respondError, having only the writer,
+// reads it back.
+func Locale(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		locale := i18n.Negotiate(r.Header.Get("Accept-Language"))
+		w.Header().Set("Content-Language", locale)
+		w.Header().Add("Vary", "Accept-Language")
+		next.ServeHTTP(w, r.WithContext(i18n.WithLocale(r.Context(), locale)))
+	})
+}
+
+// writeError writes the error body the handlers use, with the message in the
+// request's locale.
+func writeError(w http.ResponseWriter, r *http.Request, status int, key i18n.Key) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"error": map[string]string{"message": i18n.Message(i18n.FromContext(r.Context()), key)},
+	})
+}
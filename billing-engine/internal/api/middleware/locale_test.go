@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"billing-engine/internal/pkg/i18n"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocale(t *testing.T) {
+	var seen string
+	handler := Locale(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = i18n.FromContext(r.Context())
+		writeError(w, r, http.StatusForbidden, i18n.MsgForbidden)
+	}))
+
+	t.Run("negotiates Indonesian", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/loans", nil)
+		req.Header.Set("Accept-Language", "id-ID,id;q=0.9,en;q=0.5")
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, i18n.Indonesian, seen)
+		assert.Equal(t, "id", rec.Header().Get("Content-Language"))
+		assert.Equal(t, "Accept-Language", rec.Header().Get("Vary"))
+		assert.JSONEq(t, `{"error":{"message":"Akses ditolak"}}`, rec.Body.String())
+	})
+
+	t.Run("falls back to English", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/loans", nil)
+		req.Header.Set("Accept-Language", "fr-CH, fr;q=0.9")
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, "en", rec.Header().Get("Content-Language"))
+		assert.JSONEq(t, `{"error":{"message":"Forbidden"}}`, rec.Body.String())
+	})
+}
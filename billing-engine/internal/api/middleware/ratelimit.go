@@ -2,26 +2,63 @@ package middleware
 
 import (
 	"billing-engine/internal/config"
-	"encoding/json"
+	"billing-engine/internal/pkg/i18n"
+	"billing-engine/internal/pkg/scope"
+	"billing-engine/internal/ratelimit"
+	"context"
 	"log/slog"
 	"net"
 	"net/http"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"golang.org/x/time/rate"
 )
 
+// limiterIdleTimeout is how long a client goes without a request before its
+// limiter and counts are dropped.
+const limiterIdleTimeout = 10 * time.Minute
+
+// rateLimitDecisions has no client label: the number of client IPs has no
+// bound, so per-client counts are served by TopConsumers instead.
+var rateLimitDecisions = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "billing_engine_ratelimit_requests_total",
+	Help: "Total number of requests by rate limiter decision: allowed, limited, blocked or allowlisted.",
+}, []string{"decision"})
+
 type RateLimiterMiddleware struct {
 	limiters sync.Map
 	cfg      config.RateLimitConfig
+	limits   RateLimitSource
+	tenantOf func(*http.Request) string
+	access   *ratelimit.AccessList
 	logger   *slog.Logger
 }
 
-func NewRateLimiterMiddleware(cfg config.RateLimitConfig, logger *slog.Logger) *RateLimiterMiddleware {
+// RateLimitSource gives the per-client rate and burst when they can change
+// while the service runs, as tenant settings do.
+type RateLimitSource interface {
+	RateLimit(ctx context.Context) (rps float64, burst int, err error)
+}
+
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	allowed  atomic.Uint64
+	limited  atomic.Uint64
+	lastSeen atomic.Int64
+}
+
+// NewRateLimiterMiddleware limits requests per client IP. access may be nil,
+// in which case no client is blocked or allowlisted.
+func NewRateLimiterMiddleware(cfg config.RateLimitConfig, access *ratelimit.AccessList, logger *slog.Logger) *RateLimiterMiddleware {
 	rl := &RateLimiterMiddleware{
 		cfg:    cfg,
+		access: access,
 		logger: logger,
 	}
 
@@ -30,24 +67,55 @@ func NewRateLimiterMiddleware(cfg config.RateLimitConfig, logger *slog.Logger) *
 	return rl
 }
 
-func (rl *RateLimiterMiddleware) getLimiter(ip string) *rate.Limiter {
-	limiter, exists := rl.limiters.Load(ip)
-	if !exists {
-		newLimiter := rate.NewLimiter(rate.Limit(rl.cfg.RPS), rl.cfg.Burst)
-		rl.limiters.Store(ip, newLimiter)
-		return newLimiter
+// UseLimits takes the rate and burst from src on every request instead of
+// from the configuration; whether requests are limited at all is still
+// configured. src is asked under the tenant tenantOf names for the request,
+// since the limiter runs before authentication. Call it before the
+// middleware serves.
+func (rl *RateLimiterMiddleware) UseLimits(src RateLimitSource, tenantOf func(*http.Request) string) {
+	rl.limits = src
+	rl.tenantOf = tenantOf
+}
+
+// currentLimits falls back to the configured rate and burst when the source
+// fails, so that a settings outage does not lift or tighten the limit.
+func (rl *RateLimiterMiddleware) currentLimits(ctx context.Context) (float64, int) {
+	if rl.limits == nil {
+		return rl.cfg.RPS, rl.cfg.Burst
+	}
+	rps, burst, err := rl.limits.RateLimit(ctx)
+	if err != nil {
+		rl.logger.Warn("Failed to read the rate limit, using the configured one", "error", err)
+		return rl.cfg.RPS, rl.cfg.Burst
+	}
+	return rps, burst
+}
+
+// getLimiter returns the client's limiter, brought to rps and burst if they
+// changed since it was made.
+func (rl *RateLimiterMiddleware) getLimiter(ip string, rps float64, burst int) *clientLimiter {
+	if v, ok := rl.limiters.Load(ip); ok {
+		cl := v.(*clientLimiter)
+		if cl.limiter.Limit() != rate.Limit(rps) {
+			cl.limiter.SetLimit(rate.Limit(rps))
+		}
+		if cl.limiter.Burst() != burst {
+			cl.limiter.SetBurst(burst)
+		}
+		return cl
 	}
-	return limiter.(*rate.Limiter)
+	cl, _ := rl.limiters.LoadOrStore(ip, &clientLimiter{limiter: rate.NewLimiter(rate.Limit(rps), burst)})
+	return cl.(*clientLimiter)
 }
 
 func (rl *RateLimiterMiddleware) cleanupLimiters() {
-	ticker := time.NewTicker(10 * time.Minute)
+	ticker := time.NewTicker(limiterIdleTimeout)
 	defer ticker.Stop()
 
 	for range ticker.C {
+		idleSince := time.Now().Add(-limiterIdleTimeout).UnixNano()
 		rl.limiters.Range(func(key, value interface{}) bool {
-			limiter := value.(*rate.Limiter)
-			if limiter.AllowN(time.Now(), 0) {
+			if value.(*clientLimiter).lastSeen.Load() < idleSince {
 				rl.limiters.Delete(key)
 			}
 			return true
@@ -55,7 +123,36 @@ func (rl *RateLimiterMiddleware) cleanupLimiters() {
 	}
 }
 
-func (rl *RateLimiterMiddleware) extractIP(r *http.Request) string {
+// TopConsumers returns up to n clients with the most requests, busiest
+// first. Clients are counted from their first request after being idle for
+// limiterIdleTimeout; allowlisted and blocked clients are not counted.
+func (rl *RateLimiterMiddleware) TopConsumers(n int) []ratelimit.Consumer {
+	var consumers []ratelimit.Consumer
+	rl.limiters.Range(func(key, value interface{}) bool {
+		cl := value.(*clientLimiter)
+		consumers = append(consumers, ratelimit.Consumer{
+			Principal: key.(string),
+			Allowed:   cl.allowed.Load(),
+			Limited:   cl.limited.Load(),
+			LastSeen:  time.Unix(0, cl.lastSeen.Load()),
+		})
+		return true
+	})
+	sort.Slice(consumers, func(i, j int) bool {
+		if consumers[i].Requests() != consumers[j].Requests() {
+			return consumers[i].Requests() > consumers[j].Requests()
+		}
+		return consumers[i].Principal < consumers[j].Principal
+	})
+	if len(consumers) > n {
+		consumers = consumers[:n]
+	}
+	return consumers
+}
+
+// clientIP is the address a request came from, as the proxies in front
+// report it.
+func clientIP(r *http.Request) string {
 	xff := r.Header.Get("X-Forwarded-For")
 	if xff != "" {
 		ips := strings.Split(xff, ",")
@@ -75,25 +172,48 @@ func (rl *RateLimiterMiddleware) extractIP(r *http.Request) string {
 }
 
 func (rl *RateLimiterMiddleware) Middleware(next http.Handler) http.Handler {
-	if !rl.cfg.Enabled {
+	if !rl.cfg.Enabled && rl.access == nil {
 		return next
 	}
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ip := rl.extractIP(r)
-		limiter := rl.getLimiter(ip)
+		ip := clientIP(r)
 
-		if !limiter.Allow() {
+		access := ratelimit.Unlisted
+		if rl.access != nil {
+			access = rl.access.Lookup(ip)
+		}
+		switch {
+		case access == ratelimit.Blocked:
+			rateLimitDecisions.WithLabelValues("blocked").Inc()
+			rl.logger.Warn("Blocked client refused", "ip", ip)
+			writeError(w, r, http.StatusForbidden, i18n.MsgClientBlocked)
+			return
+		case access == ratelimit.Allowed:
+			rateLimitDecisions.WithLabelValues("allowlisted").Inc()
+			next.ServeHTTP(w, r)
+			return
+		case !rl.cfg.Enabled:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := r.Context()
+		if rl.tenantOf != nil {
+			ctx = scope.WithTenant(ctx, rl.tenantOf(r))
+		}
+		rps, burst := rl.currentLimits(ctx)
+		cl := rl.getLimiter(ip, rps, burst)
+		cl.lastSeen.Store(time.Now().UnixNano())
+		if !cl.limiter.Allow() {
+			cl.limited.Add(1)
+			rateLimitDecisions.WithLabelValues("limited").Inc()
 			rl.logger.Warn("Rate limit exceeded", "ip", ip)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusTooManyRequests)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"error": map[string]string{
-					"message": "Rate limit exceeded",
-				},
-			})
+			writeError(w, r, http.StatusTooManyRequests, i18n.MsgRateLimited)
 			return
 		}
+		cl.allowed.Add(1)
+		rateLimitDecisions.WithLabelValues("allowed").Inc()
 
 		next.ServeHTTP(w, r)
 	})
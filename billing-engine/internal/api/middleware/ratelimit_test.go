@@ -2,12 +2,21 @@ package middleware
 
 import (
 	"billing-engine/internal/config"
+	"billing-engine/internal/pkg/scope"
+	"billing-engine/internal/ratelimit"
+	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
 )
 
 const (
@@ -24,7 +33,7 @@ func TestRateLimiterMiddleware(t *testing.T) {
 		Burst:   2,
 	}
 
-	middleware := NewRateLimiterMiddleware(cfg, logger)
+	middleware := NewRateLimiterMiddleware(cfg, nil, logger)
 
 	t.Run("allows requests under the rate limit", func(t *testing.T) {
 		nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -77,24 +86,24 @@ func TestRateLimiterMiddleware(t *testing.T) {
 		}
 	})
 
-	t.Run("extractIP handles various headers", func(t *testing.T) {
+	t.Run("clientIP handles various headers", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/", nil)
 		req.Header.Set("X-Forwarded-For", "192.168.1.1, 10.0.0.1")
-		ip := middleware.extractIP(req)
+		ip := clientIP(req)
 		if ip != "192.168.1.1" {
 			t.Errorf(expectedIP, "192.168.1.1", ip)
 		}
 
 		req = httptest.NewRequest(http.MethodGet, "/", nil)
 		req.Header.Set("X-Real-IP", "10.0.0.1")
-		ip = middleware.extractIP(req)
+		ip = clientIP(req)
 		if ip != "10.0.0.1" {
 			t.Errorf(expectedIP, "10.0.0.1", ip)
 		}
 
 		req = httptest.NewRequest(http.MethodGet, "/", nil)
 		req.RemoteAddr = remoteAddr
-		ip = middleware.extractIP(req)
+		ip = clientIP(req)
 		if ip != "127.0.0.1" {
 			t.Errorf(expectedIP, "127.0.0.1", ip)
 		}
@@ -123,3 +132,120 @@ func TestRateLimiterMiddleware(t *testing.T) {
 // 		t.Errorf("expected limiter for IP %s to be cleaned up", ip)
 // 	}
 // }
+
+func TestRateLimiterAccessLists(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	access := ratelimit.NewAccessList(ratelimit.NewMemoryStore(), 0, logger)
+	require.NoError(t, access.Add(context.Background(), ratelimit.Blocklist, "203.0.113.9"))
+	require.NoError(t, access.Add(context.Background(), ratelimit.Allowlist, "10.0.0.7"))
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	serve := func(h http.Handler, ip string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = ip + ":40000"
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	t.Run("refuses blocked clients even with rate limiting off", func(t *testing.T) {
+		before := testutil.ToFloat64(rateLimitDecisions.WithLabelValues("blocked"))
+		h := NewRateLimiterMiddleware(config.RateLimitConfig{}, access, logger).Middleware(next)
+
+		assert.Equal(t, http.StatusForbidden, serve(h, "203.0.113.9"))
+		assert.Equal(t, http.StatusOK, serve(h, "198.51.100.4"))
+		assert.Equal(t, before+1, testutil.ToFloat64(rateLimitDecisions.WithLabelValues("blocked")))
+	})
+
+	t.Run("lets allowlisted clients past the limit", func(t *testing.T) {
+		rl := NewRateLimiterMiddleware(config.RateLimitConfig{Enabled: true, RPS: 1, Burst: 1}, access, logger)
+		h := rl.Middleware(next)
+
+		for range 3 {
+			assert.Equal(t, http.StatusOK, serve(h, "10.0.0.7"))
+		}
+		assert.Empty(t, rl.TopConsumers(10), "allowlisted clients are not counted")
+	})
+
+	t.Run("ranks clients by requests", func(t *testing.T) {
+		rl := NewRateLimiterMiddleware(config.RateLimitConfig{Enabled: true, RPS: 1, Burst: 2}, access, logger)
+		h := rl.Middleware(next)
+		for range 4 {
+			serve(h, "198.51.100.4")
+		}
+		serve(h, "198.51.100.5")
+
+		top := rl.TopConsumers(10)
+
+		require.Len(t, top, 2)
+		assert.Equal(t, "198.51.100.4", top[0].Principal)
+		assert.Equal(t, uint64(2), top[0].Allowed)
+		assert.Equal(t, uint64(2), top[0].Limited)
+		assert.Equal(t, uint64(1), top[1].Requests())
+		assert.Len(t, rl.TopConsumers(1), 1)
+	})
+}
+
+// stubLimits hands out rps and burst, or fails with err.
+type stubLimits struct {
+	rps   float64
+	burst int
+	err   error
+}
+
+func (s *stubLimits) RateLimit(context.Context) (float64, int, error) {
+	return s.rps, s.burst, s.err
+}
+
+func TestRateLimiterUseLimits(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	serve := func(h http.Handler) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	t.Run("takes the limit from the source and follows its changes", func(t *testing.T) {
+		limits := &stubLimits{rps: 0.001, burst: 1}
+		rl := NewRateLimiterMiddleware(config.RateLimitConfig{Enabled: true, RPS: 0.001, Burst: 5}, nil, logger)
+		rl.UseLimits(limits, nil)
+		h := rl.Middleware(next)
+
+		assert.Equal(t, http.StatusOK, serve(h))
+		assert.Equal(t, http.StatusTooManyRequests, serve(h))
+
+		limits.rps = float64(rate.Inf)
+		assert.Equal(t, http.StatusOK, serve(h), "a raised rate applies to a client already seen")
+	})
+
+	t.Run("a failing source leaves the configured limit", func(t *testing.T) {
+		rl := NewRateLimiterMiddleware(config.RateLimitConfig{Enabled: true, RPS: 0.001, Burst: 2}, nil, logger)
+		rl.UseLimits(&stubLimits{err: errors.New("settings unavailable")}, nil)
+		h := rl.Middleware(next)
+
+		assert.Equal(t, http.StatusOK, serve(h))
+		assert.Equal(t, http.StatusOK, serve(h))
+		assert.Equal(t, http.StatusTooManyRequests, serve(h))
+	})
+
+	t.Run("asks the source for the caller's tenant", func(t *testing.T) {
+		rl := NewRateLimiterMiddleware(config.RateLimitConfig{Enabled: true, RPS: 0.001, Burst: 1}, nil, logger)
+		rl.UseLimits(tenantLimits{"acme": 3}, func(*http.Request) string { return "acme" })
+		h := rl.Middleware(next)
+
+		for range 3 {
+			assert.Equal(t, http.StatusOK, serve(h))
+		}
+		assert.Equal(t, http.StatusTooManyRequests, serve(h))
+	})
+}
+
+// tenantLimits is a burst per tenant at a negligible rate.
+type tenantLimits map[string]int
+
+func (l tenantLimits) RateLimit(ctx context.Context) (float64, int, error) {
+	return 0.001, l[scope.TenantOrDefault(ctx)], nil
+}
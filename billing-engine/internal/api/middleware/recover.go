@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"billing-engine/internal/infrastructure/errorreport"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/i18n"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Recoverer turns a handler panic into a 500 with the usual error body, logs
+// it with its stack and request ID, and sends it to reporter unless that is
+// nil. Reports go out in the background so the client is not kept waiting
+// on the tracker, and the log record is marked as reported so that an
+// errorreport.Handler does not send it a second time. http.ErrAbortHandler
+// is passed on, as net/http expects.
+func Recoverer(reporter errorreport.Reporter, logger *slog.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+
+				ctx := r.Context()
+				event := errorreport.Event{
+					Level:     errorreport.LevelFatal,
+					Message:   fmt.Sprintf("panic: %v", rec),
+					Stack:     string(debug.Stack()),
+					RequestID: middleware.GetReqID(ctx),
+					Method:    r.Method,
+					URL:       r.URL.String(),
+					Time:      time.Now(),
+				}
+				logger.ErrorContext(errorreport.MarkReported(ctx), "Handler panicked",
+					slog.String("panic", fmt.Sprint(rec)),
+					slog.String("request_id", event.RequestID),
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+					slog.String("stack", event.Stack),
+				)
+				if reporter != nil {
+					go func() {
+						if err := reporter.Report(context.WithoutCancel(ctx), event); err != nil {
+							logger.Warn("Failed to report handler panic", slog.String("request_id", event.RequestID), slog.Any("error", err))
+						}
+					}()
+				}
+
+				if r.Header.Get("Connection") == "Upgrade" {
+					return
+				}
+				// Locale runs inside this middleware, so the language is
+				// negotiated here again.
+				locale := i18n.Negotiate(r.Header.Get("Accept-Language"))
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("Content-Language", locale)
+				w.WriteHeader(http.StatusInternalServerError)
+				_ = json.NewEncoder(w).Encode(map[string]any{
+					"error": map[string]string{"code": string(apperrors.CodeInternal), "message": i18n.Message(locale, i18n.MsgInternal)},
+				})
+			}()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
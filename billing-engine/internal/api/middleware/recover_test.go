@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"billing-engine/internal/infrastructure/errorreport"
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type reporterFunc func(context.Context, errorreport.Event) error
+
+func (f reporterFunc) Report(ctx context.Context, event errorreport.Event) error {
+	return f(ctx, event)
+}
+
+func TestRecoverer(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	panics := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		var loans []int
+		_ = loans[3]
+	})
+
+	t.Run("answers 500 and reports the panic", func(t *testing.T) {
+		var logs bytes.Buffer
+		reported := make(chan errorreport.Event, 1)
+		reporter := reporterFunc(func(_ context.Context, event errorreport.Event) error {
+			reported <- event
+			return nil
+		})
+		handler := middleware.RequestID(Recoverer(reporter, slog.New(slog.NewTextHandler(&logs, nil)))(panics))
+		req := httptest.NewRequest(http.MethodGet, "/loans/7", nil)
+		req.Header.Set("Accept-Language", "id")
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.JSONEq(t, `{"error":{"code":"INTERNAL","message":"Terjadi kesalahan yang tidak terduga."}}`, rec.Body.String())
+		select {
+		case event := <-reported:
+			assert.Contains(t, event.Message, "index out of range")
+			assert.Contains(t, event.Stack, "recover_test.go")
+			assert.NotEmpty(t, event.RequestID)
+			assert.Equal(t, "/loans/7", event.URL)
+			assert.Contains(t, logs.String(), event.RequestID)
+		case <-time.After(time.Second):
+			t.Fatal("the panic was not reported")
+		}
+		assert.Contains(t, logs.String(), "Handler panicked")
+	})
+
+	t.Run("works without a reporter", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+
+		Recoverer(nil, logger)(panics).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/loans/7", nil))
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+
+	t.Run("passes on an aborted handler", func(t *testing.T) {
+		aborts := http.HandlerFunc(func(http.ResponseWriter, *http.Request) { panic(http.ErrAbortHandler) })
+
+		require.PanicsWithValue(t, http.ErrAbortHandler, func() {
+			Recoverer(nil, logger)(aborts).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		})
+	})
+}
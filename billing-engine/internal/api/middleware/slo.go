@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"billing-engine/internal/infrastructure/monitoring"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/traceid"
+)
+
+// SLOMiddleware records every routed request in the route metrics and in
+// tracker. A 5xx response counts against the error budget; client errors do
+// not. Requests that matched no route are left out so that scanners cannot
+// grow the label set, and so are the routes in skip, given as
+// "METHOD pattern" without the API version, such as long-lived streams
+// whose duration is not latency.
+func SLOMiddleware(tracker *monitoring.SLOTracker, skip []string) func(next http.Handler) http.Handler {
+	skipped := make(map[string]bool, len(skip))
+	for _, route := range skip {
+		skipped[route] = true
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+			defer func() {
+				// A panic becomes a 500 in Recoverer further out, after
+				// this function has run.
+				rvr := recover()
+				if route := chi.RouteContext(r.Context()).RoutePattern(); route != "" && !skipped[r.Method+" "+UnversionedPattern(route)] {
+					duration := time.Since(start)
+					failed := rvr != nil || ww.Status() >= http.StatusInternalServerError
+					monitoring.RecordRouteRequest(r.Method, route, failed, duration, traceid.FromContext(r.Context()))
+					tracker.Record(r.Method, route, failed, duration, start.Add(duration))
+				}
+				if rvr != nil {
+					panic(rvr)
+				}
+			}()
+
+			next.ServeHTTP(ww, r)
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"billing-engine/internal/infrastructure/monitoring"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	chimw "github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSLOMiddleware(t *testing.T) {
+	tracker := monitoring.NewSLOTracker(time.Hour, 0.99)
+	r := chi.NewRouter()
+	r.Use(chimw.Recoverer)
+	r.Use(SLOMiddleware(tracker, []string{"GET /events/stream"}))
+	r.Get("/loans/{loanID}", func(w http.ResponseWriter, r *http.Request) {
+		switch chi.URLParam(r, "loanID") {
+		case "missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "broken":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "panic":
+			panic("boom")
+		default:
+			w.Write([]byte("ok"))
+		}
+	})
+	r.Get("/events/stream", func(w http.ResponseWriter, r *http.Request) {})
+
+	for _, path := range []string{"/loans/1", "/loans/missing", "/loans/broken", "/loans/panic", "/events/stream", "/unknown"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	summary := tracker.Summary(time.Now())
+	require.Len(t, summary.Routes, 1, "skipped and unmatched routes are not tracked")
+	assert.Equal(t, "/loans/{loanID}", summary.Routes[0].Route)
+	assert.Equal(t, uint64(4), summary.Routes[0].Requests)
+	assert.Equal(t, uint64(2), summary.Routes[0].Errors, "the 503 and the panic count, the 404 does not")
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+)
+
+type streamRowLimitKey struct{}
+
+// StreamRowLimit caps how many lines an NDJSON export sends in one response.
+// The export handlers stop there and the client resumes with the cursor of
+// the last line. Zero or less leaves exports uncapped.
+func StreamRowLimit(maxRows int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if maxRows <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), streamRowLimitKey{}, maxRows)))
+		})
+	}
+}
+
+// StreamRowLimitFromContext returns the cap StreamRowLimit set, 0 for none.
+func StreamRowLimitFromContext(ctx context.Context) int {
+	maxRows, _ := ctx.Value(streamRowLimitKey{}).(int)
+	return maxRows
+}
@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamRowLimit(t *testing.T) {
+	var seen int
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = StreamRowLimitFromContext(r.Context())
+	})
+
+	StreamRowLimit(500)(next).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/loans", nil))
+	assert.Equal(t, 500, seen)
+
+	StreamRowLimit(0)(next).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/loans", nil))
+	assert.Equal(t, 0, seen, "zero leaves exports uncapped")
+}
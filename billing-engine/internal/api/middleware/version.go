@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+)
+
+// APIVersionHeader names the version of the API that served a response.
+const APIVersionHeader = "API-Version"
+
+type apiVersionKey struct{}
+
+// apiVersion is how a request reached one version of the API: its name,
+// such as v1, and the path prefix it was called with, empty on the legacy
+// unprefixed routes.
+type apiVersion struct {
+	name   string
+	prefix string
+}
+
+// APIVersion marks the requests to the routes of version, which are mounted
+// below "/"+version. Responses say which version served them.
+func APIVersion(version string) func(http.Handler) http.Handler {
+	return withAPIVersion(apiVersion{name: version, prefix: "/" + version})
+}
+
+func withAPIVersion(v apiVersion) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(APIVersionHeader, v.name)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiVersionKey{}, v)))
+		})
+	}
+}
+
+// VersionPrefix is the path prefix the request reached its version with,
+// such as /v1, to put in front of the paths sent back to the client so that
+// it stays on the version it called. It is empty on the legacy routes and
+// outside the versioned routes.
+func VersionPrefix(ctx context.Context) string {
+	v, _ := ctx.Value(apiVersionKey{}).(apiVersion)
+	return v.prefix
+}
+
+var versionPrefix = regexp.MustCompile(`^/v[0-9]+(/|$)`)
+
+// UnversionedPattern drops the version prefix from a route pattern, so that
+// "/v1/loans/{loanID}" and the legacy "/loans/{loanID}" are configured and
+// exempted as one route.
+func UnversionedPattern(pattern string) string {
+	if loc := versionPrefix.FindStringIndex(pattern); loc != nil {
+		return "/" + pattern[loc[1]:]
+	}
+	return pattern
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIVersion(t *testing.T) {
+	var prefix string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prefix = VersionPrefix(r.Context())
+	})
+
+	t.Run("versioned routes", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		APIVersion("v2")(next).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v2/loans/3", nil))
+
+		assert.Equal(t, "/v2", prefix)
+		assert.Equal(t, "v2", rec.Header().Get(APIVersionHeader))
+		assert.Empty(t, rec.Header().Get("Deprecation"))
+	})
+
+	t.Run("outside the API", func(t *testing.T) {
+		next.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+		assert.Empty(t, prefix)
+	})
+}
+
+func TestUnversionedPattern(t *testing.T) {
+	for pattern, want := range map[string]string{
+		"/v1/loans/{loanID}": "/loans/{loanID}",
+		"/v12/jobs":          "/jobs",
+		"/v1":                "/",
+		"/loans/{loanID}":    "/loans/{loanID}",
+		"/version":           "/version",
+	} {
+		assert.Equal(t, want, UnversionedPattern(pattern), pattern)
+	}
+}
@@ -0,0 +1,294 @@
+package openapi
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GenerateClient renders the typed Go client for doc: one struct per
+// component schema and one method per operation, named by operation ID.
+// Operations that do not answer with JSON, such as the event stream, are
+// skipped.
+func GenerateClient(doc *Document, pkg string) ([]byte, error) {
+	g := &clientGenerator{imports: map[string]bool{"context": true}}
+
+	var body bytes.Buffer
+	g.writeTypes(&body, doc.Components.Schemas)
+	for _, op := range doc.Operations() {
+		if resp := successResponse(op.Responses); resp != nil && resp.Content != nil {
+			if _, ok := resp.Content["application/json"]; !ok {
+				continue
+			}
+		}
+		if err := g.writeOperation(&body, op); err != nil {
+			return nil, err
+		}
+	}
+
+	var out bytes.Buffer
+	out.WriteString("// Code generated by cmd/openapi from the OpenAPI document. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&out, "package %s\n\n", pkg)
+	out.WriteString("import (\n")
+	imports := make([]string, 0, len(g.imports))
+	for imp := range g.imports {
+		imports = append(imports, imp)
+	}
+	sort.Strings(imports)
+	for _, imp := range imports {
+		fmt.Fprintf(&out, "\t%q\n", imp)
+	}
+	out.WriteString(")\n")
+	out.Write(body.Bytes())
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format generated client: %w", err)
+	}
+	return src, nil
+}
+
+type clientGenerator struct {
+	imports map[string]bool
+}
+
+func (g *clientGenerator) writeTypes(buf *bytes.Buffer, schemas map[string]*Schema) {
+	for _, name := range sortedKeys(schemas) {
+		schema := schemas[name]
+		required := map[string]bool{}
+		for _, r := range schema.Required {
+			required[r] = true
+		}
+
+		fmt.Fprintf(buf, "\ntype %s struct {\n", name)
+		for _, prop := range sortedKeys(schema.Properties) {
+			propSchema := schema.Properties[prop]
+			typ := g.goType(propSchema)
+			if propSchema.Nullable {
+				typ = "*" + typ
+			}
+			tag := prop
+			if !required[prop] {
+				tag += ",omitempty"
+			}
+			fmt.Fprintf(buf, "\t%s %s `json:%q`\n", goIdent(prop, true), typ, tag)
+		}
+		buf.WriteString("}\n")
+	}
+}
+
+func (g *clientGenerator) writeOperation(buf *bytes.Buffer, op OperationRef) error {
+	var (
+		args     = []string{"ctx context.Context"}
+		pathExpr []string
+		query    []string
+	)
+
+	for _, segment := range strings.Split(strings.TrimPrefix(op.Path, "/"), "/") {
+		if !strings.HasPrefix(segment, "{") {
+			pathExpr = appendLiteral(pathExpr, "/"+segment)
+			continue
+		}
+		name := strings.Trim(segment, "{}")
+		param := findParam(op.Parameters, name, "path")
+		if param == nil {
+			return fmt.Errorf("operation %s: path parameter %q is not declared", op.OperationID, name)
+		}
+		ident := goIdent(name, false)
+		args = append(args, ident+" "+g.goType(param.Schema))
+		pathExpr = appendLiteral(pathExpr, "/")
+		pathExpr = append(pathExpr, g.formatValue(ident, param.Schema))
+	}
+
+	for _, param := range op.Parameters {
+		if param.In != "query" {
+			continue
+		}
+		ident := goIdent(param.Name, false)
+		args = append(args, ident+" "+g.goType(param.Schema))
+		set := fmt.Sprintf("query.Set(%q, %s)", param.Name, g.formatValue(ident, param.Schema))
+		if !param.Required {
+			set = fmt.Sprintf("if %s {\n%s\n}", isSet(ident, param.Schema), set)
+		}
+		query = append(query, set)
+	}
+
+	bodyArg := "nil"
+	if op.RequestBody != nil {
+		if media, ok := op.RequestBody.Content["application/json"]; ok {
+			args = append(args, "req "+g.goType(media.Schema))
+			bodyArg = "req"
+		} else {
+			// Uploads such as CSV files are streamed as is with the caller's
+			// content type.
+			g.imports["io"] = true
+			args = append(args, "contentType string", "body io.Reader")
+			bodyArg = "rawBody{contentType: contentType, r: body}"
+		}
+	}
+
+	var result *Schema
+	if resp := successResponse(op.Responses); resp != nil && resp.Content != nil {
+		result = resp.Content["application/json"].Schema
+	}
+
+	fmt.Fprintf(buf, "\n// %s calls %s %s: %s.\n", op.OperationID, op.Method, op.Path, op.Summary)
+	returns := "error"
+	if result != nil {
+		returns = fmt.Sprintf("(%s, error)", g.resultType(result))
+	}
+	fmt.Fprintf(buf, "func (c *Client) %s(%s) %s {\n", op.OperationID, strings.Join(args, ", "), returns)
+
+	queryArg := "nil"
+	if len(query) > 0 {
+		g.imports["net/url"] = true
+		buf.WriteString("query := url.Values{}\n")
+		buf.WriteString(strings.Join(query, "\n") + "\n")
+		queryArg = "query"
+	}
+	call := fmt.Sprintf("c.do(ctx, %q, %s, %s, %s", op.Method, strings.Join(pathExpr, " + "), queryArg, bodyArg)
+
+	switch {
+	case result == nil:
+		fmt.Fprintf(buf, "return %s, nil)\n", call)
+	case result.Ref != "":
+		fmt.Fprintf(buf, "var out %s\nif err := %s, &out); err != nil {\nreturn nil, err\n}\nreturn &out, nil\n", g.goType(result), call)
+	default:
+		fmt.Fprintf(buf, "var out %s\nif err := %s, &out); err != nil {\nreturn nil, err\n}\nreturn out, nil\n", g.goType(result), call)
+	}
+	buf.WriteString("}\n")
+	return nil
+}
+
+func (g *clientGenerator) goType(s *Schema) string {
+	if s.Ref != "" {
+		return strings.TrimPrefix(s.Ref, schemaRefPrefix)
+	}
+	switch s.Type {
+	case "string":
+		if s.Format == "date-time" {
+			g.imports["time"] = true
+			return "time.Time"
+		}
+		return "string"
+	case "boolean":
+		return "bool"
+	case "integer":
+		switch s.Format {
+		case "int64":
+			return "int64"
+		case "int32":
+			return "int32"
+		}
+		return "int"
+	case "number":
+		if s.Format == "float" {
+			return "float32"
+		}
+		return "float64"
+	case "array":
+		return "[]" + g.goType(s.Items)
+	case "object":
+		if s.AdditionalProperties != nil {
+			return "map[string]" + g.goType(s.AdditionalProperties)
+		}
+	}
+	return "any"
+}
+
+func (g *clientGenerator) resultType(s *Schema) string {
+	if s.Ref != "" {
+		return "*" + g.goType(s)
+	}
+	return g.goType(s)
+}
+
+func (g *clientGenerator) formatValue(ident string, s *Schema) string {
+	switch g.goType(s) {
+	case "string":
+		return ident
+	case "int64":
+		g.imports["strconv"] = true
+		return fmt.Sprintf("strconv.FormatInt(%s, 10)", ident)
+	case "int":
+		g.imports["strconv"] = true
+		return fmt.Sprintf("strconv.Itoa(%s)", ident)
+	case "bool":
+		g.imports["strconv"] = true
+		return fmt.Sprintf("strconv.FormatBool(%s)", ident)
+	}
+	g.imports["fmt"] = true
+	return fmt.Sprintf("fmt.Sprint(%s)", ident)
+}
+
+// isSet is the condition under which an optional query parameter is sent.
+func isSet(ident string, s *Schema) string {
+	switch s.Type {
+	case "string":
+		return ident + ` != ""`
+	case "boolean":
+		return ident
+	}
+	return ident + " != 0"
+}
+
+func successResponse(responses map[string]*Response) *Response {
+	for code := 200; code < 300; code++ {
+		if resp, ok := responses[strconv.Itoa(code)]; ok {
+			return resp
+		}
+	}
+	return nil
+}
+
+func findParam(params []Parameter, name, in string) *Parameter {
+	for i := range params {
+		if params[i].Name == name && params[i].In == in {
+			return &params[i]
+		}
+	}
+	return nil
+}
+
+func appendLiteral(expr []string, lit string) []string {
+	if n := len(expr); n > 0 && strings.HasPrefix(expr[n-1], `"`) {
+		prev, _ := strconv.Unquote(expr[n-1])
+		expr[n-1] = strconv.Quote(prev + lit)
+		return expr
+	}
+	return append(expr, strconv.Quote(lit))
+}
+
+// goIdent turns a JSON or parameter name such as loan_id or customerId into a
+// Go identifier, keeping the ID initialism upper case.
+func goIdent(name string, exported bool) string {
+	parts := strings.Split(name, "_")
+	for i, p := range parts {
+		if p == "" {
+			continue
+		}
+		if i > 0 || exported {
+			p = strings.ToUpper(p[:1]) + p[1:]
+		}
+		if strings.HasSuffix(p, "Id") {
+			p = strings.TrimSuffix(p, "Id") + "ID"
+		}
+		if p == "id" && exported {
+			p = "ID"
+		}
+		parts[i] = p
+	}
+	return strings.Join(parts, "")
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
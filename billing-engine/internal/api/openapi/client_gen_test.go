@@ -0,0 +1,26 @@
+package openapi
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateClientUpToDate(t *testing.T) {
+	src, err := GenerateClient(Build(), "client")
+	require.NoError(t, err)
+
+	onDisk, err := os.ReadFile("../../../pkg/client/client_gen.go")
+	require.NoError(t, err)
+	assert.Equal(t, string(onDisk), string(src), "pkg/client is stale, run make openapi")
+}
+
+func TestGoIdent(t *testing.T) {
+	assert.Equal(t, "LoanID", goIdent("loanId", true))
+	assert.Equal(t, "ID", goIdent("id", true))
+	assert.Equal(t, "loanID", goIdent("loan_id", false))
+	assert.Equal(t, "customerID", goIdent("customerID", false))
+	assert.Equal(t, "IsDelinquent", goIdent("isDelinquent", true))
+}
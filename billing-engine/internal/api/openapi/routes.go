@@ -0,0 +1,756 @@
+package openapi
+
+import (
+	"billing-engine/internal/api/handler/dto"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const securityScheme = "BearerAuth"
+
+// Route describes one endpoint served by the router. Request and Response
+// hold zero values of the DTO types so their schemas can be derived.
+type Route struct {
+	Method      string
+	Path        string
+	OperationID string
+	Summary     string
+	Tag         string
+	Query       []QueryParam
+	Request     any
+	// RequestContentTypes of the request body, application/json when empty.
+	RequestContentTypes []string
+	Status              int
+	Response            any
+	Errors              []int
+	Public              bool
+	// ContentType of the success response, application/json when empty.
+	ContentType string
+	// Stream is the line schema of the NDJSON variant served to clients that
+	// send Accept: application/x-ndjson.
+	Stream any
+	// Accepted is the job returned with 202 when the request is processed in
+	// the background.
+	Accepted any
+}
+
+const streamDescription = "OK. With Accept: application/x-ndjson the rows are streamed one per line in ID order; " +
+	"pass the ID of the last line received as the cursor query parameter to resume."
+
+const acceptedDescription = "Accepted. The request is processed by an async job; poll the URL in the Location header. " +
+	"Sent for uploads above bulk.syncMaxRows rows and to clients that send Prefer: respond-async."
+
+type QueryParam struct {
+	Name     string
+	Type     any
+	Required bool
+}
+
+var statusDescriptions = map[int]string{
+	http.StatusBadRequest:            "Invalid request",
+	http.StatusUnauthorized:          "Missing or invalid bearer token",
+	http.StatusForbidden:             "Token scope does not allow this operation",
+	http.StatusNotFound:              "Resource not found",
+	http.StatusConflict:              "Resource conflicts with existing data",
+	http.StatusRequestEntityTooLarge: "Request body exceeds the size limit",
+	http.StatusInternalServerError:   "Internal server error",
+	http.StatusServiceUnavailable:    "Dependent service is not configured or unavailable",
+}
+
+// subjectParams are the path parameters that take a numeric ID or a public
+// UUID. Other path parameters are plain numeric IDs.
+var subjectParams = map[string]bool{"customerID": true, "loanID": true}
+
+// textParams are the path parameters that are not IDs, with their
+// description.
+var textParams = map[string]string{"principal": "Client IP address", "jobID": "Job ID", "name": "Batch job name, e.g. DelinquencyUpdate", "tenantID": "Tenant ID, default"}
+
+// Routes is the source of truth for the published API surface. A router test
+// fails when a mounted route is missing here.
+func Routes() []Route {
+	staffErrors := []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError}
+	createErrors := append([]int{http.StatusConflict}, staffErrors...)
+	selfServiceErrors := []int{http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError}
+	attachmentErrors := append([]int{http.StatusServiceUnavailable}, staffErrors...)
+	adminErrors := []int{http.StatusUnauthorized, http.StatusForbidden, http.StatusInternalServerError, http.StatusServiceUnavailable}
+
+	routes := []Route{
+		{
+			Method: http.MethodPost, Path: "/auth/token", OperationID: "GenerateToken", Tag: "Authentication",
+			Summary: "Generate a JWT bearer token",
+			Request: dto.TokenRequest{}, Status: http.StatusOK, Response: map[string]string{},
+			Errors: []int{http.StatusBadRequest, http.StatusForbidden, http.StatusInternalServerError}, Public: true,
+		},
+		{
+			Method: http.MethodPost, Path: "/customers", OperationID: "CreateCustomer", Tag: "Customers",
+			Summary: "Create a new customer",
+			Request: dto.CreateCustomerRequest{}, Status: http.StatusCreated, Response: dto.CustomerResponse{}, Errors: createErrors,
+		},
+		{
+			Method: http.MethodGet, Path: "/customers", OperationID: "FindCustomerByLoan", Tag: "Customers",
+			Summary: "Find customer by loan ID or external reference",
+			Query:   []QueryParam{{Name: "loan_id", Type: int64(0)}, {Name: "external_ref", Type: ""}},
+			Status:  http.StatusOK, Response: dto.CustomerResponse{}, Errors: staffErrors,
+		},
+		{
+			Method: http.MethodPost, Path: "/customers/import", OperationID: "ImportCustomers", Tag: "Customers",
+			Summary: "Import customers in bulk",
+			Request: "", RequestContentTypes: []string{"text/csv", "application/x-ndjson"},
+			Status: http.StatusOK, Response: dto.CustomerImportResponse{}, Accepted: dto.JobResponse{},
+			Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusInternalServerError, http.StatusServiceUnavailable},
+		},
+		{
+			Method: http.MethodGet, Path: "/customers/{customerID}", OperationID: "GetCustomer", Tag: "Customers",
+			Summary: "Retrieve customer details",
+			Status:  http.StatusOK, Response: dto.CustomerResponse{}, Errors: staffErrors,
+		},
+		{
+			Method: http.MethodDelete, Path: "/customers/{customerID}", OperationID: "DeactivateCustomer", Tag: "Customers",
+			Summary: "Deactivate a customer",
+			Status:  http.StatusNoContent, Errors: staffErrors,
+		},
+		{
+			Method: http.MethodPut, Path: "/customers/{customerID}/address", OperationID: "UpdateCustomerAddress", Tag: "Customers",
+			Summary: "Update customer address",
+			Request: dto.UpdateCustomerAddressRequest{}, Status: http.StatusNoContent, Errors: staffErrors,
+		},
+		{
+			Method: http.MethodPut, Path: "/customers/{customerID}/loan", OperationID: "AssignLoanToCustomer", Tag: "Customers",
+			Summary: "Assign a loan to a customer",
+			Request: dto.AssignLoanRequest{}, Status: http.StatusNoContent, Errors: staffErrors,
+		},
+		{
+			Method: http.MethodPut, Path: "/customers/{customerID}/delinquency", OperationID: "UpdateDelinquency", Tag: "Customers",
+			Summary: "Update customer delinquency status",
+			Request: dto.UpdateDelinquencyRequest{}, Status: http.StatusNoContent, Errors: staffErrors,
+		},
+		{
+			Method: http.MethodPut, Path: "/customers/{customerID}/reactivate", OperationID: "ReactivateCustomer", Tag: "Customers",
+			Summary: "Reactivate a customer",
+			Status:  http.StatusNoContent, Errors: staffErrors,
+		},
+		{
+			Method: http.MethodGet, Path: "/customers/{customerID}/preferences", OperationID: "GetCustomerPreferences", Tag: "Customers",
+			Summary: "Get customer communication preferences",
+			Status:  http.StatusOK, Response: dto.PreferencesResponse{}, Errors: staffErrors,
+		},
+		{
+			Method: http.MethodPut, Path: "/customers/{customerID}/preferences", OperationID: "UpdateCustomerPreferences", Tag: "Customers",
+			Summary: "Replace customer communication preferences",
+			Request: dto.UpdatePreferencesRequest{}, Status: http.StatusOK, Response: dto.PreferencesResponse{}, Errors: staffErrors,
+		},
+		{
+			Method: http.MethodPut, Path: "/customers/{customerID}/risk-score", OperationID: "UpdateCustomerRiskScore", Tag: "Customers",
+			Summary: "Store the risk score a credit bureau gave a customer",
+			Request: dto.UpdateRiskScoreRequest{}, Status: http.StatusOK, Response: dto.CustomerResponse{},
+			Errors: append([]int{http.StatusBadRequest, http.StatusNotFound}, adminErrors...),
+		},
+		{
+			Method: http.MethodPost, Path: "/customers/{customerID}/merge", OperationID: "MergeCustomer", Tag: "Customers",
+			Summary: "Merge a duplicate customer into this one",
+			Request: dto.MergeCustomerRequest{}, Status: http.StatusOK, Response: dto.CustomerMergeResponse{},
+			Errors: append([]int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict}, adminErrors...),
+		},
+		{
+			Method: http.MethodGet, Path: "/customers/{customerID}/summary", OperationID: "GetCustomerSummary", Tag: "Customers",
+			Summary: "Get a customer's loan summary",
+			Status:  http.StatusOK, Response: dto.CustomerSummaryResponse{}, Errors: staffErrors,
+		},
+		{
+			Method: http.MethodGet, Path: "/customers/{customerID}/overview", OperationID: "GetCustomerOverview", Tag: "Customers",
+			Summary: "Get a customer's overview for support",
+			Status:  http.StatusOK, Response: dto.CustomerOverviewResponse{}, Errors: staffErrors,
+		},
+		{
+			Method: http.MethodGet, Path: "/customers/{customerID}/contacts", OperationID: "ListContacts", Tag: "Contacts",
+			Summary: "List the phone numbers and email addresses of a customer",
+			Status:  http.StatusOK, Response: []dto.ContactResponse{}, Errors: staffErrors,
+		},
+		{
+			Method: http.MethodPost, Path: "/customers/{customerID}/contacts", OperationID: "CreateContact", Tag: "Contacts",
+			Summary: "Add an unverified phone number or email address",
+			Request: dto.CreateContactRequest{}, Status: http.StatusCreated, Response: dto.ContactResponse{}, Errors: createErrors,
+		},
+		{
+			Method: http.MethodPut, Path: "/customers/{customerID}/contacts/{contactID}", OperationID: "UpdateContact", Tag: "Contacts",
+			Summary: "Change a contact's value or make it primary",
+			Request: dto.UpdateContactRequest{}, Status: http.StatusOK, Response: dto.ContactResponse{}, Errors: createErrors,
+		},
+		{
+			Method: http.MethodDelete, Path: "/customers/{customerID}/contacts/{contactID}", OperationID: "DeleteContact", Tag: "Contacts",
+			Summary: "Delete a contact",
+			Status:  http.StatusNoContent, Errors: staffErrors,
+		},
+		{
+			Method: http.MethodPost, Path: "/customers/{customerID}/contacts/{contactID}/verification", OperationID: "SendContactVerification", Tag: "Contacts",
+			Summary: "Send a verification code to a contact",
+			Status:  http.StatusAccepted, Response: dto.ContactVerificationResponse{},
+			Errors: append([]int{http.StatusConflict, http.StatusServiceUnavailable}, staffErrors...),
+		},
+		{
+			Method: http.MethodPost, Path: "/customers/{customerID}/contacts/{contactID}/verification/confirm", OperationID: "ConfirmContactVerification", Tag: "Contacts",
+			Summary: "Verify a contact with the code sent to it",
+			Request: dto.ConfirmContactRequest{}, Status: http.StatusOK, Response: dto.ContactResponse{}, Errors: createErrors,
+		},
+		{
+			Method: http.MethodPost, Path: "/customers/{customerID}/mandates", OperationID: "CreateMandate", Tag: "Direct Debit",
+			Summary: "Register a direct-debit mandate",
+			Request: dto.CreateMandateRequest{}, Status: http.StatusCreated, Response: dto.MandateResponse{}, Errors: createErrors,
+		},
+		{
+			Method: http.MethodGet, Path: "/customers/{customerID}/mandates", OperationID: "ListMandates", Tag: "Direct Debit",
+			Summary: "List the direct-debit mandates of a customer",
+			Status:  http.StatusOK, Response: []dto.MandateResponse{}, Errors: staffErrors,
+		},
+		{
+			Method: http.MethodDelete, Path: "/customers/{customerID}/mandates/{mandateID}", OperationID: "CancelMandate", Tag: "Direct Debit",
+			Summary: "Cancel a direct-debit mandate",
+			Status:  http.StatusNoContent, Errors: staffErrors,
+		},
+		{
+			Method: http.MethodPost, Path: "/direct-debit/results", OperationID: "ProcessDirectDebitResults", Tag: "Direct Debit",
+			Summary: "Process a bank result file",
+			Request: "", RequestContentTypes: []string{"text/csv"},
+			Status: http.StatusOK, Response: dto.DirectDebitResultsResponse{}, Accepted: dto.JobResponse{},
+			Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusInternalServerError, http.StatusServiceUnavailable},
+		},
+		{
+			Method: http.MethodGet, Path: "/jobs", OperationID: "ListJobs", Tag: "Jobs",
+			Summary: "List async jobs, newest first",
+			Query:   []QueryParam{{Name: "kind", Type: ""}, {Name: "status", Type: ""}, {Name: "limit", Type: 0}},
+			Status:  http.StatusOK, Response: []dto.JobResponse{},
+			Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusInternalServerError, http.StatusServiceUnavailable},
+		},
+		{
+			Method: http.MethodGet, Path: "/jobs/{jobID}", OperationID: "GetJob", Tag: "Jobs",
+			Summary: "Get the progress and result of an async job",
+			Status:  http.StatusOK, Response: dto.JobResponse{},
+			Errors: []int{http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError, http.StatusServiceUnavailable},
+		},
+		{
+			Method: http.MethodGet, Path: "/collections/queue", OperationID: "GetCollectionQueue", Tag: "Collections",
+			Summary: "Get a collector's queue",
+			Query:   []QueryParam{{Name: "collector", Type: ""}},
+			Status:  http.StatusOK, Response: []dto.CollectionAssignmentResponse{}, Errors: staffErrors,
+		},
+		{
+			Method: http.MethodPost, Path: "/collections/rules", OperationID: "CreateCollectionRule", Tag: "Collections",
+			Summary: "Create a collection rule",
+			Request: dto.CreateCollectionRuleRequest{}, Status: http.StatusCreated, Response: dto.CollectionRuleResponse{}, Errors: staffErrors,
+		},
+		{
+			Method: http.MethodGet, Path: "/collections/rules", OperationID: "ListCollectionRules", Tag: "Collections",
+			Summary: "List collection rules",
+			Status:  http.StatusOK, Response: []dto.CollectionRuleResponse{}, Errors: staffErrors,
+		},
+		{
+			Method: http.MethodDelete, Path: "/collections/rules/{ruleID}", OperationID: "DeleteCollectionRule", Tag: "Collections",
+			Summary: "Delete a collection rule",
+			Status:  http.StatusNoContent, Errors: staffErrors,
+		},
+		{
+			Method: http.MethodPost, Path: "/collections/assignments/{assignmentID}/actions", OperationID: "RecordCollectionAction", Tag: "Collections",
+			Summary: "Record a collection action",
+			Request: dto.RecordCollectionActionRequest{}, Status: http.StatusCreated, Response: dto.CollectionActionResponse{}, Errors: createErrors,
+		},
+		{
+			Method: http.MethodGet, Path: "/collections/assignments/{assignmentID}/actions", OperationID: "ListCollectionActions", Tag: "Collections",
+			Summary: "List collection actions",
+			Status:  http.StatusOK, Response: []dto.CollectionActionResponse{}, Errors: staffErrors,
+		},
+		{
+			Method: http.MethodPut, Path: "/collections/assignments/{assignmentID}/collector", OperationID: "ReassignCollection", Tag: "Collections",
+			Summary: "Reassign a loan to another collector",
+			Request: dto.ReassignCollectionRequest{}, Status: http.StatusOK, Response: dto.CollectionAssignmentResponse{}, Errors: createErrors,
+		},
+		{
+			Method: http.MethodGet, Path: "/collections/escalation-steps", OperationID: "ListEscalationSteps", Tag: "Collections",
+			Summary: "List the reminder ladder",
+			Status:  http.StatusOK, Response: []dto.EscalationStepResponse{}, Errors: staffErrors,
+		},
+		{
+			Method: http.MethodPut, Path: "/collections/escalation-steps", OperationID: "ReplaceEscalationSteps", Tag: "Collections",
+			Summary: "Replace the reminder ladder",
+			Request: dto.ReplaceEscalationStepsRequest{}, Status: http.StatusOK, Response: []dto.EscalationStepResponse{},
+			Errors: append([]int{http.StatusBadRequest}, adminErrors...),
+		},
+		{
+			Method: http.MethodPost, Path: "/loans", OperationID: "CreateLoan", Tag: "Loans",
+			Summary: "Create a new loan",
+			Request: dto.CreateLoanRequest{}, Status: http.StatusCreated, Response: dto.LoanResponse{}, Errors: createErrors,
+		},
+		{
+			Method: http.MethodGet, Path: "/loans", OperationID: "FindLoanByExternalRef", Tag: "Loans",
+			Summary: "Find loan by external reference",
+			Query:   []QueryParam{{Name: "external_ref", Type: "", Required: true}, {Name: "include", Type: ""}},
+			Status:  http.StatusOK, Response: dto.LoanResponse{}, Stream: dto.LoanResponse{}, Errors: staffErrors,
+		},
+		{
+			Method: http.MethodGet, Path: "/loans/search", OperationID: "SearchLoans", Tag: "Loans",
+			Summary: "Search loans by payment reference, amount or customer name",
+			Query: []QueryParam{
+				{Name: "reference", Type: ""}, {Name: "amount", Type: 0.0}, {Name: "tolerance", Type: 0.0},
+				{Name: "from", Type: ""}, {Name: "to", Type: ""}, {Name: "customer_name", Type: ""}, {Name: "limit", Type: 0},
+			},
+			Status: http.StatusOK, Response: []dto.LoanSearchMatchResponse{}, Errors: staffErrors,
+		},
+		{
+			Method: http.MethodGet, Path: "/loans/{loanID}", OperationID: "GetLoan", Tag: "Loans",
+			Summary: "Retrieve loan details",
+			Query:   []QueryParam{{Name: "include", Type: ""}},
+			Status:  http.StatusOK, Response: dto.LoanResponse{}, Errors: staffErrors,
+		},
+		{
+			Method: http.MethodGet, Path: "/loans/{loanID}/outstanding", OperationID: "GetOutstanding", Tag: "Loans",
+			Summary: "Retrieve outstanding loan amount",
+			Status:  http.StatusOK, Response: dto.OutstandingResponse{}, Errors: staffErrors,
+		},
+		{
+			Method: http.MethodGet, Path: "/loans/{loanID}/delinquent", OperationID: "IsDelinquent", Tag: "Loans",
+			Summary: "Check loan delinquency status",
+			Status:  http.StatusOK, Response: dto.DelinquentResponse{}, Errors: staffErrors,
+		},
+		{
+			Method: http.MethodPost, Path: "/loans/{loanID}/payments", OperationID: "MakePayment", Tag: "Loans",
+			Summary: "Make a loan payment",
+			Request: dto.MakePaymentRequest{}, Status: http.StatusOK, Response: map[string]string{}, Errors: createErrors,
+		},
+		{
+			Method: http.MethodPost, Path: "/loans/{loanID}/prepayments", OperationID: "PrepayLoan", Tag: "Loans",
+			Summary: "Prepay principal and reamortize the remaining schedule",
+			Request: dto.PrepaymentRequest{}, Status: http.StatusCreated, Response: dto.ReamortizationResponse{}, Errors: createErrors,
+		},
+		{
+			Method: http.MethodPost, Path: "/loans/{loanID}/restructure/preview", OperationID: "PreviewLoanRestructure", Tag: "Loans",
+			Summary: "Preview the schedule and terms a restructure would give a loan",
+			Request: dto.RestructurePreviewRequest{}, Status: http.StatusOK, Response: dto.RestructurePreviewResponse{}, Errors: createErrors,
+		},
+		{
+			Method: http.MethodGet, Path: "/loans/fee-types", OperationID: "ListFeeTypes", Tag: "Loans",
+			Summary: "List the fee catalog",
+			Status:  http.StatusOK, Response: []dto.FeeTypeResponse{}, Errors: []int{http.StatusUnauthorized, http.StatusForbidden},
+		},
+		{
+			Method: http.MethodPost, Path: "/loans/{loanID}/fees", OperationID: "PostLoanFee", Tag: "Loans",
+			Summary: "Post an ad-hoc fee to a loan",
+			Request: dto.PostFeeRequest{}, Status: http.StatusCreated, Response: dto.FeeResponse{}, Errors: createErrors,
+		},
+		{
+			Method: http.MethodGet, Path: "/loans/{loanID}/fees", OperationID: "ListLoanFees", Tag: "Loans",
+			Summary: "List a loan's fees and their waivers",
+			Status:  http.StatusOK, Response: []dto.FeeResponse{}, Errors: staffErrors,
+		},
+		{
+			Method: http.MethodPost, Path: "/loans/{loanID}/fees/{feeID}/waiver", OperationID: "RequestFeeWaiver", Tag: "Loans",
+			Summary: "Request a fee waiver",
+			Request: dto.FeeWaiverRequest{}, Status: http.StatusCreated, Response: dto.FeeWaiverResponse{}, Errors: createErrors,
+		},
+		{
+			Method: http.MethodPost, Path: "/loans/{loanID}/fees/{feeID}/waiver/approve", OperationID: "ApproveFeeWaiver", Tag: "Loans",
+			Summary: "Approve a pending fee waiver",
+			Status:  http.StatusOK, Response: dto.FeeWaiverResponse{}, Errors: createErrors,
+		},
+		{
+			Method: http.MethodPost, Path: "/loans/{loanID}/fees/{feeID}/waiver/reject", OperationID: "RejectFeeWaiver", Tag: "Loans",
+			Summary: "Reject a pending fee waiver",
+			Status:  http.StatusOK, Response: dto.FeeWaiverResponse{}, Errors: createErrors,
+		},
+		{
+			Method: http.MethodPost, Path: "/loans/{loanID}/hold", OperationID: "PlaceLoanHold", Tag: "Loans",
+			Summary: "Place a loan on hold",
+			Request: dto.PlaceHoldRequest{}, Status: http.StatusCreated, Response: dto.LoanHoldResponse{}, Errors: createErrors,
+		},
+		{
+			Method: http.MethodDelete, Path: "/loans/{loanID}/hold", OperationID: "ReleaseLoanHold", Tag: "Loans",
+			Summary: "Release a loan hold",
+			Status:  http.StatusOK, Response: dto.LoanHoldResponse{}, Errors: staffErrors,
+		},
+		{
+			Method: http.MethodPost, Path: "/loans/{loanID}/rate", OperationID: "RepriceLoan", Tag: "Loans",
+			Summary: "Change a loan's interest rate from an effective date",
+			Request: dto.RepriceLoanRequest{}, Status: http.StatusCreated, Response: dto.RepricingResponse{}, Errors: createErrors,
+		},
+		{
+			Method: http.MethodPost, Path: "/loans/{loanID}/adjustments", OperationID: "ApplyScheduleAdjustment", Tag: "Loans",
+			Summary: "Grant a payment holiday or a promotional zero-interest window",
+			Request: dto.ApplyAdjustmentRequest{}, Status: http.StatusCreated, Response: dto.AdjustmentPlanResponse{}, Errors: createErrors,
+		},
+		{
+			Method: http.MethodDelete, Path: "/loans/{loanID}/adjustments/{adjustmentID}", OperationID: "RemoveScheduleAdjustment", Tag: "Loans",
+			Summary: "Withdraw a schedule adjustment that has not started",
+			Status:  http.StatusOK, Response: dto.AdjustmentPlanResponse{}, Errors: createErrors,
+		},
+		{
+			Method: http.MethodGet, Path: "/loans/{loanID}/history", OperationID: "GetLoanHistory", Tag: "Loans",
+			Summary: "Retrieve daily loan status snapshots",
+			Query:   []QueryParam{{Name: "from", Type: ""}, {Name: "to", Type: ""}},
+			Status:  http.StatusOK, Response: dto.LoanHistoryResponse{}, Errors: staffErrors,
+		},
+		{
+			Method: http.MethodGet, Path: "/reports/portfolio", OperationID: "GetPortfolio", Tag: "Reports",
+			Summary: "Retrieve the portfolio as of a date",
+			Query:   []QueryParam{{Name: "date", Type: ""}},
+			Status:  http.StatusOK, Response: dto.PortfolioResponse{}, Stream: dto.PortfolioLoanResponse{}, Errors: staffErrors,
+		},
+		{
+			Method: http.MethodGet, Path: "/reports/collections-by-channel", OperationID: "GetCollectionsByChannel", Tag: "Reports",
+			Summary: "Total the payments received by channel",
+			Query:   []QueryParam{{Name: "from", Type: ""}, {Name: "to", Type: ""}},
+			Status:  http.StatusOK, Response: dto.CollectionsByChannelResponse{}, Errors: staffErrors,
+		},
+		{
+			Method: http.MethodGet, Path: "/reports/tax", OperationID: "GetTaxReport", Tag: "Reports",
+			Summary: "Total the tax charged on fees by jurisdiction and fee type",
+			Query:   []QueryParam{{Name: "from", Type: ""}, {Name: "to", Type: ""}},
+			Status:  http.StatusOK, Response: dto.TaxReportResponse{}, Errors: staffErrors,
+		},
+	}
+	routes = append(routes, noteRoutes("/customers/{customerID}", "Customer", staffErrors, attachmentErrors)...)
+	routes = append(routes, noteRoutes("/loans/{loanID}", "Loan", staffErrors, attachmentErrors)...)
+	routes = append(routes, documentRoutes("/customers/{customerID}", "Customer", staffErrors, attachmentErrors)...)
+	routes = append(routes, documentRoutes("/loans/{loanID}", "Loan", staffErrors, attachmentErrors)...)
+	routes = append(routes, agreementRoutes(staffErrors, createErrors)...)
+	return append(routes, []Route{
+		{
+			Method: http.MethodPost, Path: "/graphql", OperationID: "GraphQLQuery", Tag: "GraphQL",
+			Summary: "Execute a read-only GraphQL query",
+			Request: dto.GraphQLRequest{}, Status: http.StatusOK, Response: dto.GraphQLResponse{},
+			Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden},
+		},
+		{
+			Method: http.MethodGet, Path: "/events/stream", OperationID: "StreamEvents", Tag: "Events",
+			Summary: "Stream billing events",
+			Query:   []QueryParam{{Name: "types", Type: ""}, {Name: "access_token", Type: ""}},
+			Status:  http.StatusOK, Response: "", ContentType: "text/event-stream",
+			Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden},
+		},
+		{
+			Method: http.MethodGet, Path: "/admin/sandbox/clock", OperationID: "GetSandboxClock", Tag: "Sandbox",
+			Summary: "Get the sandbox billing clock",
+			Status:  http.StatusOK, Response: dto.SandboxClockResponse{}, Errors: adminErrors,
+		},
+		{
+			Method: http.MethodPost, Path: "/admin/sandbox/clock/advance", OperationID: "AdvanceSandboxClock", Tag: "Sandbox",
+			Summary: "Advance the sandbox billing clock and run the daily jobs",
+			Request: dto.AdvanceClockRequest{}, Status: http.StatusOK, Response: dto.SandboxClockResponse{},
+			Errors: append([]int{http.StatusBadRequest}, adminErrors...),
+		},
+		{
+			Method: http.MethodGet, Path: "/admin/events", OperationID: "ListRecordedEvents", Tag: "Events",
+			Summary: "List recorded events a replay with the same criteria would publish",
+			Query: []QueryParam{{Name: "entity_id", Type: int64(0)}, {Name: "types", Type: ""}, {Name: "since", Type: ""},
+				{Name: "until", Type: ""}, {Name: "unpublished", Type: false}, {Name: "limit", Type: 0}},
+			Status: http.StatusOK, Response: []dto.EventRecordResponse{},
+			Errors: append([]int{http.StatusBadRequest}, adminErrors...),
+		},
+		{
+			Method: http.MethodPost, Path: "/admin/events/replay", OperationID: "ReplayEvents", Tag: "Events",
+			Summary: "Publish recorded events to the broker again",
+			Request: dto.ReplayEventsRequest{}, Status: http.StatusOK, Response: dto.ReplayReportResponse{}, Accepted: dto.JobResponse{},
+			Errors: append([]int{http.StatusBadRequest}, adminErrors...),
+		},
+		{
+			Method: http.MethodPost, Path: "/admin/loans/{loanID}/schedule/rebuild", OperationID: "RebuildLoanSchedule", Tag: "Loans",
+			Summary: "Regenerate a loan's unpaid installments from its terms, or preview the changes with dry_run",
+			Query:   []QueryParam{{Name: "dry_run", Type: false}},
+			Status:  http.StatusOK, Response: dto.ScheduleRebuildResponse{},
+			Errors: append([]int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict}, adminErrors...),
+		},
+		{
+			Method: http.MethodPost, Path: "/admin/loans/repricing", OperationID: "RepriceLoans", Tag: "Loans",
+			Summary: "Apply a base-rate change to the loans charged a rate, or to every loan not paid off",
+			Request: dto.RepriceLoansRequest{}, Status: http.StatusOK, Response: dto.RepricingReportResponse{}, Accepted: dto.JobResponse{},
+			Errors: append([]int{http.StatusBadRequest}, adminErrors...),
+		},
+		{
+			Method: http.MethodGet, Path: "/admin/slo", OperationID: "GetSLOSummary", Tag: "Monitoring",
+			Summary: "Get rolling latency quantiles and error budget burn per route",
+			Status:  http.StatusOK, Response: dto.SLOSummaryResponse{}, Errors: adminErrors,
+		},
+		{
+			Method: http.MethodGet, Path: "/admin/deprecations", OperationID: "ListDeprecatedUsage", Tag: "Monitoring",
+			Summary: "List the clients still calling deprecated routes and how often",
+			Query:   []QueryParam{{Name: "limit", Type: 0}},
+			Status:  http.StatusOK, Response: []dto.DeprecatedUsageResponse{},
+			Errors: append([]int{http.StatusBadRequest}, adminErrors...),
+		},
+		{
+			Method: http.MethodGet, Path: "/admin/integrity/findings", OperationID: "ListIntegrityFindings", Tag: "Monitoring",
+			Summary: "List the data integrity violations found by the last integrity check run",
+			Query:   []QueryParam{{Name: "check", Type: ""}},
+			Status:  http.StatusOK, Response: []dto.IntegrityFindingResponse{},
+			Errors: append([]int{http.StatusBadRequest}, adminErrors...),
+		},
+		{
+			Method: http.MethodGet, Path: "/admin/jobs/{name}/runs", OperationID: "ListJobRuns", Tag: "Monitoring",
+			Summary: "List the latest runs of a batch job with their progress, error samples and duration",
+			Query:   []QueryParam{{Name: "limit", Type: 0}},
+			Status:  http.StatusOK, Response: []dto.JobRunResponse{},
+			Errors: append([]int{http.StatusBadRequest, http.StatusNotFound, http.StatusServiceUnavailable}, adminErrors...),
+		},
+		{
+			Method: http.MethodGet, Path: "/admin/jobs/{name}/runs/{runID}", OperationID: "GetJobRun", Tag: "Monitoring",
+			Summary: "Get one run of a batch job",
+			Status:  http.StatusOK, Response: dto.JobRunResponse{},
+			Errors: append([]int{http.StatusBadRequest, http.StatusNotFound, http.StatusServiceUnavailable}, adminErrors...),
+		},
+		{
+			Method: http.MethodGet, Path: "/admin/ratelimit/consumers", OperationID: "ListRateLimitConsumers", Tag: "Rate Limiting",
+			Summary: "List the client IPs with the most requests and how many were rate limited",
+			Query:   []QueryParam{{Name: "limit", Type: 0}},
+			Status:  http.StatusOK, Response: []dto.RateLimitConsumerResponse{},
+			Errors: append([]int{http.StatusBadRequest}, adminErrors...),
+		},
+		{
+			Method: http.MethodGet, Path: "/admin/ratelimit/lists", OperationID: "GetRateLimitLists", Tag: "Rate Limiting",
+			Summary: "Get the blocked and the allowlisted client IPs",
+			Status:  http.StatusOK, Response: dto.RateLimitListsResponse{}, Errors: adminErrors,
+		},
+		{
+			Method: http.MethodPut, Path: "/admin/ratelimit/blocklist/{principal}", OperationID: "BlockClient", Tag: "Rate Limiting",
+			Summary: "Refuse every request from a client IP",
+			Status:  http.StatusOK, Response: dto.RateLimitListsResponse{},
+			Errors: append([]int{http.StatusBadRequest}, adminErrors...),
+		},
+		{
+			Method: http.MethodDelete, Path: "/admin/ratelimit/blocklist/{principal}", OperationID: "UnblockClient", Tag: "Rate Limiting",
+			Summary: "Take a client IP off the blocklist",
+			Status:  http.StatusOK, Response: dto.RateLimitListsResponse{},
+			Errors: append([]int{http.StatusBadRequest}, adminErrors...),
+		},
+		{
+			Method: http.MethodPut, Path: "/admin/ratelimit/allowlist/{principal}", OperationID: "AllowlistClient", Tag: "Rate Limiting",
+			Summary: "Exempt a client IP from the rate limit",
+			Status:  http.StatusOK, Response: dto.RateLimitListsResponse{},
+			Errors: append([]int{http.StatusBadRequest}, adminErrors...),
+		},
+		{
+			Method: http.MethodDelete, Path: "/admin/ratelimit/allowlist/{principal}", OperationID: "RemoveAllowlistedClient", Tag: "Rate Limiting",
+			Summary: "Rate limit a client IP again",
+			Status:  http.StatusOK, Response: dto.RateLimitListsResponse{},
+			Errors: append([]int{http.StatusBadRequest}, adminErrors...),
+		},
+		{
+			Method: http.MethodGet, Path: "/admin/tenants/{tenantID}/settings", OperationID: "GetTenantSettings", Tag: "Admin",
+			Summary: "Get the delinquency thresholds and rate limit a tenant overrides",
+			Status:  http.StatusOK, Response: dto.TenantSettingsResponse{},
+			Errors: append([]int{http.StatusNotFound}, adminErrors...),
+		},
+		{
+			Method: http.MethodPut, Path: "/admin/tenants/{tenantID}/settings", OperationID: "UpdateTenantSettings", Tag: "Admin",
+			Summary: "Replace a tenant's overrides of the delinquency thresholds and rate limit",
+			Request: dto.UpdateTenantSettingsRequest{}, Status: http.StatusOK, Response: dto.TenantSettingsResponse{},
+			Errors: append([]int{http.StatusBadRequest, http.StatusNotFound}, adminErrors...),
+		},
+		{
+			Method: http.MethodGet, Path: "/me/loans", OperationID: "MyLoans", Tag: "Self Service",
+			Summary: "List my loans",
+			Status:  http.StatusOK, Response: []dto.LoanResponse{}, Errors: selfServiceErrors,
+		},
+		{
+			Method: http.MethodGet, Path: "/me/schedule", OperationID: "MySchedule", Tag: "Self Service",
+			Summary: "Retrieve my repayment schedule",
+			Status:  http.StatusOK, Response: []dto.ScheduleEntryResponse{}, Errors: selfServiceErrors,
+		},
+		{
+			Method: http.MethodGet, Path: "/me/outstanding", OperationID: "MyOutstanding", Tag: "Self Service",
+			Summary: "Retrieve my outstanding amount",
+			Status:  http.StatusOK, Response: dto.OutstandingResponse{}, Errors: selfServiceErrors,
+		},
+	}...)
+}
+
+// noteRoutes documents the notes and attachments routes mounted below a loan
+// or customer. subject names the operations, e.g. CreateLoanNote.
+func noteRoutes(base, subject string, staffErrors, attachmentErrors []int) []Route {
+	lower := strings.ToLower(subject)
+	return []Route{
+		{
+			Method: http.MethodPost, Path: base + "/notes", OperationID: "Create" + subject + "Note", Tag: "Notes",
+			Summary: "Add a note to a " + lower,
+			Request: dto.CreateNoteRequest{}, Status: http.StatusCreated, Response: dto.NoteResponse{}, Errors: staffErrors,
+		},
+		{
+			Method: http.MethodGet, Path: base + "/notes", OperationID: "List" + subject + "Notes", Tag: "Notes",
+			Summary: "List the notes of a " + lower,
+			Status:  http.StatusOK, Response: []dto.NoteResponse{}, Errors: staffErrors,
+		},
+		{
+			Method: http.MethodDelete, Path: base + "/notes/{noteID}", OperationID: "Delete" + subject + "Note", Tag: "Notes",
+			Summary: "Delete a note of a " + lower,
+			Status:  http.StatusNoContent, Errors: staffErrors,
+		},
+		{
+			Method: http.MethodPost, Path: base + "/attachments", OperationID: "Create" + subject + "Attachment", Tag: "Notes",
+			Summary: "Upload an attachment to a " + lower,
+			Request: "", RequestContentTypes: []string{"multipart/form-data"},
+			Status: http.StatusCreated, Response: dto.AttachmentResponse{}, Errors: attachmentErrors,
+		},
+		{
+			Method: http.MethodGet, Path: base + "/attachments", OperationID: "List" + subject + "Attachments", Tag: "Notes",
+			Summary: "List the attachments of a " + lower,
+			Status:  http.StatusOK, Response: []dto.AttachmentResponse{}, Errors: staffErrors,
+		},
+		{
+			Method: http.MethodDelete, Path: base + "/attachments/{attachmentID}", OperationID: "Delete" + subject + "Attachment", Tag: "Notes",
+			Summary: "Delete an attachment of a " + lower,
+			Status:  http.StatusNoContent, Errors: attachmentErrors,
+		},
+	}
+}
+
+// agreementRoutes documents the routes of loans created in workflow mode.
+// The callback is made by the e-signature provider, which signs its body
+// instead of sending a bearer token.
+func agreementRoutes(staffErrors, createErrors []int) []Route {
+	providerErrors := append([]int{http.StatusServiceUnavailable}, createErrors...)
+	return []Route{
+		{
+			Method: http.MethodPost, Path: "/loans/workflow", OperationID: "CreateWorkflowLoan", Tag: "Agreements",
+			Summary: "Create a loan on hold and send its agreement for e-signature",
+			Request: dto.CreateLoanRequest{}, Status: http.StatusCreated, Response: dto.WorkflowLoanResponse{}, Errors: providerErrors,
+		},
+		{
+			Method: http.MethodGet, Path: "/loans/{loanID}/agreement", OperationID: "GetLoanAgreement", Tag: "Agreements",
+			Summary: "Get the most recent agreement of a workflow loan",
+			Status:  http.StatusOK, Response: dto.AgreementResponse{}, Errors: staffErrors,
+		},
+		{
+			Method: http.MethodPost, Path: "/loans/{loanID}/agreement", OperationID: "SendLoanAgreement", Tag: "Agreements",
+			Summary: "Send a loan a new agreement for e-signature",
+			Status:  http.StatusOK, Response: dto.AgreementResponse{}, Errors: providerErrors,
+		},
+		{
+			Method: http.MethodPost, Path: "/loans/{loanID}/disbursement", OperationID: "DisburseLoan", Tag: "Agreements",
+			Summary: "Disburse a workflow loan whose agreement is signed",
+			Status:  http.StatusOK, Response: dto.AgreementResponse{}, Errors: createErrors,
+		},
+		{
+			Method: http.MethodPost, Path: "/esign/callback", OperationID: "HandleESignCallback", Tag: "Agreements",
+			Summary: "Apply an e-signature provider callback, signed in X-Signature",
+			Request: map[string]string{}, Status: http.StatusOK, Response: dto.AgreementResponse{},
+			Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusConflict, http.StatusServiceUnavailable}, Public: true,
+		},
+	}
+}
+
+// documentRoutes documents the documents routes mounted below a loan or
+// customer. subject names the operations, e.g. ListLoanDocuments.
+func documentRoutes(base, subject string, staffErrors, storageErrors []int) []Route {
+	lower := strings.ToLower(subject)
+	conflictErrors := append([]int{http.StatusConflict}, storageErrors...)
+	return []Route{
+		{
+			Method: http.MethodGet, Path: base + "/documents", OperationID: "List" + subject + "Documents", Tag: "Documents",
+			Summary: "List the documents of a " + lower,
+			Status:  http.StatusOK, Response: []dto.DocumentResponse{}, Errors: staffErrors,
+		},
+		{
+			Method: http.MethodPost, Path: base + "/documents", OperationID: "Upload" + subject + "Document", Tag: "Documents",
+			Summary: "Start a document upload to a " + lower + " and get its pre-signed upload link",
+			Request: dto.CreateDocumentRequest{}, Status: http.StatusCreated, Response: dto.DocumentUploadResponse{}, Errors: storageErrors,
+		},
+		{
+			Method: http.MethodGet, Path: base + "/documents/{documentID}", OperationID: "Download" + subject + "Document", Tag: "Documents",
+			Summary: "Download a " + lower + " document",
+			Status:  http.StatusOK, Response: "", ContentType: "application/pdf", Errors: conflictErrors,
+		},
+		{
+			Method: http.MethodDelete, Path: base + "/documents/{documentID}", OperationID: "Delete" + subject + "Document", Tag: "Documents",
+			Summary: "Delete an uploaded document of a " + lower,
+			Status:  http.StatusNoContent, Errors: conflictErrors,
+		},
+		{
+			Method: http.MethodPost, Path: base + "/documents/{documentID}/confirm", OperationID: "Confirm" + subject + "Document", Tag: "Documents",
+			Summary: "Confirm that the file of a pending " + lower + " document was uploaded",
+			Status:  http.StatusOK, Response: dto.DocumentResponse{}, Errors: conflictErrors,
+		},
+	}
+}
+
+func (r Route) operation(gen *schemaGenerator) *Operation {
+	op := &Operation{
+		OperationID: r.OperationID,
+		Summary:     r.Summary,
+		Tags:        []string{r.Tag},
+		Responses:   map[string]*Response{},
+	}
+
+	// Customers and loans are addressed by their numeric ID or their public
+	// UUID, so those path parameters are published as plain strings.
+	for _, name := range pathParams(r.Path) {
+		param := Parameter{Name: name, In: "path", Required: true, Schema: gen.schemaOf(int64(0))}
+		if subjectParams[name] {
+			param.Description = "Numeric ID or public UUID"
+			param.Schema = &Schema{Type: "string"}
+		} else if description, ok := textParams[name]; ok {
+			param.Description = description
+			param.Schema = &Schema{Type: "string"}
+		}
+		op.Parameters = append(op.Parameters, param)
+	}
+	for _, q := range r.Query {
+		op.Parameters = append(op.Parameters, Parameter{Name: q.Name, In: "query", Required: q.Required, Schema: gen.schemaOf(q.Type)})
+	}
+
+	if r.Request != nil {
+		contentTypes := r.RequestContentTypes
+		if len(contentTypes) == 0 {
+			contentTypes = []string{"application/json"}
+		}
+		op.RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{}}
+		for _, contentType := range contentTypes {
+			op.RequestBody.Content[contentType] = MediaType{Schema: gen.schemaOf(r.Request)}
+		}
+	}
+
+	success := &Response{Description: http.StatusText(r.Status)}
+	if r.Response != nil {
+		contentType := r.ContentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		success.Content = map[string]MediaType{contentType: {Schema: gen.schemaOf(r.Response)}}
+	}
+	if r.Stream != nil {
+		success.Description = streamDescription
+		success.Content["application/x-ndjson"] = MediaType{Schema: gen.schemaOf(r.Stream)}
+	}
+	op.Responses[strconv.Itoa(r.Status)] = success
+	if r.Accepted != nil {
+		op.Responses[strconv.Itoa(http.StatusAccepted)] = &Response{
+			Description: acceptedDescription,
+			Content:     map[string]MediaType{"application/json": {Schema: gen.schemaOf(r.Accepted)}},
+		}
+	}
+
+	errSchema := gen.schemaOf(dto.ErrorResponse{})
+	errStatuses := r.Errors
+	if r.Request != nil && len(r.RequestContentTypes) == 0 {
+		// Every JSON body passes the body limit middleware.
+		errStatuses = append(slices.Clone(errStatuses), http.StatusRequestEntityTooLarge)
+	}
+	for _, status := range errStatuses {
+		op.Responses[strconv.Itoa(status)] = &Response{
+			Description: statusDescriptions[status],
+			Content:     map[string]MediaType{"application/json": {Schema: errSchema}},
+		}
+	}
+
+	if !r.Public {
+		op.Security = []map[string][]string{{securityScheme: {}}}
+	}
+	return op
+}
+
+func pathParams(path string) []string {
+	var names []string
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			names = append(names, strings.Trim(segment, "{}"))
+		}
+	}
+	return names
+}
@@ -0,0 +1,71 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuild(t *testing.T) {
+	doc := Build()
+
+	assert.Equal(t, Version, doc.OpenAPI)
+	assert.Len(t, doc.Operations(), len(Routes()))
+
+	payment := doc.Paths["/v1/loans/{loanID}/payments"]["post"]
+	require.NotNil(t, payment)
+	assert.Equal(t, "MakePayment", payment.OperationID)
+	require.Len(t, payment.Parameters, 1)
+	assert.Equal(t, "loanID", payment.Parameters[0].Name)
+	assert.Equal(t, "path", payment.Parameters[0].In)
+	assert.Equal(t, schemaRefPrefix+"MakePaymentRequest", payment.RequestBody.Content["application/json"].Schema.Ref)
+	assert.Contains(t, payment.Responses, "404")
+	assert.Equal(t, []map[string][]string{{securityScheme: {}}}, payment.Security)
+
+	token := doc.Paths["/v1/auth/token"]["post"]
+	require.NotNil(t, token)
+	assert.Empty(t, token.Security)
+
+	upload := doc.Paths["/v1/customers/import"]["post"]
+	require.NotNil(t, upload)
+	assert.Contains(t, upload.RequestBody.Content, "text/csv")
+	assert.Contains(t, upload.RequestBody.Content, "application/x-ndjson")
+	assert.NotContains(t, upload.RequestBody.Content, "application/json")
+}
+
+func TestBuildSchemas(t *testing.T) {
+	schemas := Build().Components.Schemas
+
+	loanResp := schemas["LoanResponse"]
+	require.NotNil(t, loanResp)
+	assert.Equal(t, "date-time", loanResp.Properties["createdAt"].Format)
+	assert.Equal(t, "array", loanResp.Properties["schedule"].Type)
+	assert.Equal(t, schemaRefPrefix+"ScheduleEntryResponse", loanResp.Properties["schedule"].Items.Ref)
+	assert.NotContains(t, loanResp.Required, "schedule")
+	assert.Contains(t, loanResp.Required, "id")
+
+	entry := schemas["ScheduleEntryResponse"]
+	require.NotNil(t, entry)
+	assert.True(t, entry.Properties["paidAmount"].Nullable)
+
+	tokenReq := schemas["TokenRequest"]
+	require.NotNil(t, tokenReq)
+	assert.Equal(t, "int64", tokenReq.Properties["customerId"].Format)
+}
+
+func TestHandler(t *testing.T) {
+	rr := httptest.NewRecorder()
+	Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+	var doc Document
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &doc))
+	assert.Equal(t, "Billing Engine API", doc.Info.Title)
+	assert.Contains(t, doc.Paths, "/v1/me/outstanding")
+}
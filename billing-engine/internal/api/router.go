@@ -1,11 +1,32 @@
 package api
 
 import (
+	"billing-engine/internal/api/graphql"
 	"billing-engine/internal/api/handler"
 	mw "billing-engine/internal/api/middleware"
+	"billing-engine/internal/api/openapi"
 	"billing-engine/internal/config"
+	"billing-engine/internal/domain/agreement"
+	"billing-engine/internal/domain/collections"
+	"billing-engine/internal/domain/contact"
 	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/domain/directdebit"
+	"billing-engine/internal/domain/document"
+	"billing-engine/internal/domain/integrity"
 	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/domain/note"
+	"billing-engine/internal/domain/overview"
+	"billing-engine/internal/domain/summary"
+	"billing-engine/internal/domain/tenant"
+	"billing-engine/internal/event"
+	"billing-engine/internal/infrastructure/errorreport"
+	"billing-engine/internal/infrastructure/monitoring"
+	"billing-engine/internal/jobs"
+	"billing-engine/internal/pkg/buildinfo"
+	"billing-engine/internal/pkg/clock"
+	"billing-engine/internal/ratelimit"
+	"billing-engine/internal/sandbox"
+	"encoding/json"
 	"log/slog"
 	"net/http"
 	"time"
@@ -16,37 +37,131 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/traceid"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	httpSwagger "github.com/swaggo/http-swagger/v2"
 )
 
-func SetupRouter(loanService loan.LoanService, customerService customer.CustomerService, cfg *config.Config, logger *slog.Logger) *chi.Mux {
+// SetupRouter mounts every route. clk is the billing clock the services were
+// built with; sandboxService is nil unless sandbox mode is enabled, and a nil
+// jobRunner processes every bulk upload within its request. The handlers
+// register their job kinds on jobRunner, so start it after SetupRouter.
+// Handler panics are reported to reporter unless that is nil. The rate limit
+// is that of the tenant the caller's token names, from tenantService.
+func SetupRouter(loanService loan.LoanService, customerService customer.CustomerService, importService customer.ImportService, noteService note.Service, documentService document.Service, agreementService agreement.Service, snapshotService loan.SnapshotService, directDebitService directdebit.Service, contactService contact.Service, collectionsService collections.Service, summaryService summary.Service, overviewService overview.Service, integrityService integrity.Service, tenantService tenant.Service, hub *event.Hub, replayService event.ReplayService, clk clock.Clock, sandboxService sandbox.Service, accessList *ratelimit.AccessList, jobRunner *jobs.Runner, runRecorder *jobs.RunRecorder, reporter errorreport.Reporter, cfg *config.Config, logger *slog.Logger) *chi.Mux {
 	router := chi.NewRouter()
 
-	setupMiddleware(router, cfg, logger)
+	sloTracker := monitoring.NewSLOTracker(cfg.Server.SLO.Window, cfg.Server.SLO.Objective)
+	deprecationTracker := monitoring.NewDeprecationTracker()
+	rateLimiter := mw.NewRateLimiterMiddleware(cfg.Server.RateLimit, accessList, logger)
+	rateLimiter.UseLimits(tenant.RateLimitSource{Service: tenantService}, mw.CallerTenant(cfg.Server.Auth, logger))
+	setupMiddleware(router, sloTracker, rateLimiter, reporter, cfg, logger)
 	setupMetricsEndpoint(router, cfg, logger)
-	setupCustomerRoutes(router, cfg, customerService, logger)
-	setupLoanRoutes(router, loanService, cfg, logger)
-	router.Get("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"status":"ok"}`))
-	})
+
+	v1 := chi.NewRouter()
+	noteHandler := handler.NewNoteHandler(noteService, loanService, customerService, cfg.Storage.MaxUploadBytes, logger)
+	documentHandler := handler.NewDocumentHandler(documentService, loanService, customerService, logger)
+	bulk := handler.NewBulkRunner(jobRunner, cfg.Bulk.SyncMaxRows, logger)
+	directDebitHandler := handler.NewDirectDebitHandler(directDebitService, customerService, bulk, cfg.DirectDebit.MaxResultBytes, cfg.DirectDebit.MaxResultRows, logger)
+	contactHandler := handler.NewContactHandler(contactService, customerService, logger)
+	summaryHandler := handler.NewCustomerSummaryHandler(summaryService, customerService, logger)
+	overviewHandler := handler.NewCustomerOverviewHandler(overviewService, customerService, logger)
+	setupCustomerRoutes(v1, cfg, customerService, importService, bulk, noteHandler, documentHandler, directDebitHandler, contactHandler, summaryHandler, overviewHandler, logger)
+	setupDirectDebitRoutes(v1, directDebitHandler, cfg, logger)
+	setupJobRoutes(v1, jobRunner, cfg, logger)
+	setupCollectionsRoutes(v1, collectionsService, cfg, logger)
+	reportHandler := handler.NewReportHandler(snapshotService, loanService, clk, logger)
+	agreementHandler := handler.NewAgreementHandler(agreementService, loanService, logger)
+	setupLoanRoutes(v1, loanService, noteHandler, documentHandler, agreementHandler, reportHandler, cfg, logger)
+	setupESignRoutes(v1, agreementHandler)
+	setupReportRoutes(v1, reportHandler, cfg, logger)
+	setupSelfServiceRoutes(v1, loanService, customerService, cfg, logger)
+	setupGraphQLRoutes(v1, loanService, customerService, cfg, logger)
+	setupEventStreamRoutes(v1, hub, cfg, logger)
+	setupAdminRoutes(v1, loanService, integrityService, tenantService, sandboxService, replayService, runRecorder, bulk, sloTracker, deprecationTracker, rateLimiter, accessList, cfg, logger)
+	deprecations := mw.NewDeprecationMiddleware(cfg.Server.API, deprecationTracker)
+	mountAPIVersions(router, []apiVersion{{name: "v1", routes: v1}}, cfg.Server.API, deprecations, logger)
+
+	setupBuildEndpoints(router)
 	setupSwaggerEndpoint(router, logger)
+	setupOpenAPIEndpoint(router, logger)
 
 	return router
 }
 
-func setupMiddleware(router *chi.Mux, cfg *config.Config, logger *slog.Logger) {
+// apiVersion is one version of the API and the routes that serve it.
+type apiVersion struct {
+	name   string
+	routes *chi.Mux
+}
+
+// mountAPIVersions mounts each version's routes below its name, such as /v1,
+// oldest first. A breaking change to the request or response bodies ships as
+// a new version with its own handlers, so clients of the older version keep
+// the bodies they were written against. While cfg.LegacyRoutes is set, the
+// first version also answers on the unprefixed paths of the releases before
+// versioning, marked deprecated. deprecations tags these and the routes
+// deprecated in every version.
+func mountAPIVersions(router *chi.Mux, versions []apiVersion, cfg config.APIConfig, deprecations *mw.DeprecationMiddleware, logger *slog.Logger) {
+	for _, v := range versions {
+		logger.Info("Mounting API version", "version", v.name, "path", "/"+v.name)
+		router.With(mw.APIVersion(v.name), deprecations.Middleware).Mount("/"+v.name, v.routes)
+	}
+	if !cfg.LegacyRoutes || len(versions) == 0 {
+		return
+	}
+	legacy := versions[0]
+	logger.Info("Mounting deprecated unprefixed routes", "version", legacy.name, "sunset", cfg.LegacySunset)
+	router.With(deprecations.LegacyRoutes(legacy.name)).Mount("/", legacy.routes)
+}
+
+// setupBuildEndpoints serves /health, which also names the build, and
+// /version with the full build information. Neither needs a token, so a
+// load balancer or an operator can tell which build each instance runs.
+func setupBuildEndpoints(router *chi.Mux) {
+	build := buildinfo.Get()
+	health, _ := json.Marshal(map[string]string{"status": "ok", "version": build.Version, "commit": build.Commit})
+	version, _ := json.Marshal(build)
+	serve := func(body []byte) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write(body)
+		}
+	}
+	router.Get("/health", serve(health))
+	router.Get("/version", serve(version))
+}
+
+// uploadRoutes read multipart or streamed uploads and enforce their own size
+// limits, so the JSON body limit does not apply to them.
+var uploadRoutes = []string{
+	"POST /customers/import",
+	"POST /customers/{customerID}/attachments",
+	"POST /loans/{loanID}/attachments",
+	"POST /direct-debit/results",
+}
+
+// sloSkippedRoutes stay open for as long as the client listens, so their
+// duration says nothing about latency.
+var sloSkippedRoutes = []string{
+	"GET /events/stream",
+}
+
+func setupMiddleware(router *chi.Mux, sloTracker *monitoring.SLOTracker, rateLimiter *mw.RateLimiterMiddleware, reporter errorreport.Reporter, cfg *config.Config, logger *slog.Logger) {
 	router.Use(middleware.RequestID)
 	router.Use(middleware.RealIP)
 	router.Use(traceid.Middleware)
 	router.Use(mw.StructuredLogger(logger))
-	router.Use(middleware.Recoverer)
+	router.Use(mw.Recoverer(reporter, logger))
+	router.Use(mw.SLOMiddleware(sloTracker, sloSkippedRoutes))
 	router.Use(middleware.Compress(5))
 	router.Use(middleware.Timeout(60 * time.Second))
-	router.Use(mw.NewRateLimiterMiddleware(cfg.Server.RateLimit, logger).Middleware)
+	router.Use(mw.Locale)
+	router.Use(rateLimiter.Middleware)
+	router.Use(mw.NewBodyLimitMiddleware(cfg.Server.BodyLimit, uploadRoutes, logger).Middleware)
 	router.Use(mw.MetricsMiddleware())
+	router.Use(mw.StreamRowLimit(cfg.Bulk.MaxStreamRows))
 }
 
 func setupMetricsEndpoint(router *chi.Mux, cfg *config.Config, logger *slog.Logger) {
@@ -55,7 +170,9 @@ func setupMetricsEndpoint(router *chi.Mux, cfg *config.Config, logger *slog.Logg
 		metricsPath = "/metrics"
 	}
 	logger.Info("Setting up Prometheus metrics endpoint", "path", metricsPath)
-	router.Handle(metricsPath, promhttp.Handler())
+	// OpenMetrics carries the trace ID exemplars of the route metrics.
+	router.Handle(metricsPath, promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{EnableOpenMetrics: true})))
 }
 
 func setupSwaggerEndpoint(router *chi.Mux, logger *slog.Logger) {
@@ -66,7 +183,12 @@ func setupSwaggerEndpoint(router *chi.Mux, logger *slog.Logger) {
 	})
 }
 
-func setupLoanRoutes(router *chi.Mux, loanService loan.LoanService, cfg *config.Config, logger *slog.Logger) {
+func setupOpenAPIEndpoint(router *chi.Mux, logger *slog.Logger) {
+	logger.Info("Setting up OpenAPI document endpoint", "path", "/openapi.json")
+	router.Get("/openapi.json", openapi.Handler())
+}
+
+func setupLoanRoutes(router *chi.Mux, loanService loan.LoanService, noteHandler *handler.NoteHandler, documentHandler *handler.DocumentHandler, agreementHandler *handler.AgreementHandler, reportHandler *handler.ReportHandler, cfg *config.Config, logger *slog.Logger) {
 	loanHandler := handler.NewLoanHandler(loanService, logger)
 	authHandler := handler.NewAuthHandler(*cfg, logger)
 	logger.Info("Route Config")
@@ -76,22 +198,64 @@ func setupLoanRoutes(router *chi.Mux, loanService loan.LoanService, cfg *config.
 
 	router.Route("/loans", func(r chi.Router) {
 		r.Use(mw.AuthMiddleware(cfg.Server.Auth, logger))
+		r.Use(mw.StaffOnly(logger))
 		r.Post("/", loanHandler.CreateLoan)
+		r.Get("/", loanHandler.FindLoanByExternalRef)
+		r.Get("/fee-types", loanHandler.ListFeeTypes)
+		r.Get("/search", loanHandler.SearchLoans)
+		r.Post("/workflow", agreementHandler.CreateWorkflowLoan)
 		r.Get("/{loanID}", loanHandler.GetLoan)
 		r.Get("/{loanID}/outstanding", loanHandler.GetOutstanding)
 		r.Get("/{loanID}/delinquent", loanHandler.IsDelinquent)
 		r.Post("/{loanID}/payments", loanHandler.MakePayment)
+		r.Post("/{loanID}/prepayments", loanHandler.Prepay)
+		r.Post("/{loanID}/restructure/preview", loanHandler.PreviewRestructure)
+		r.Get("/{loanID}/fees", loanHandler.GetFees)
+		r.Post("/{loanID}/fees", loanHandler.PostFee)
+		r.Post("/{loanID}/fees/{feeID}/waiver", loanHandler.RequestFeeWaiver)
+		r.With(mw.AdminOnly(logger)).Post("/{loanID}/fees/{feeID}/waiver/approve", loanHandler.ApproveFeeWaiver)
+		r.With(mw.AdminOnly(logger)).Post("/{loanID}/fees/{feeID}/waiver/reject", loanHandler.RejectFeeWaiver)
+		r.With(mw.AdminOnly(logger)).Post("/{loanID}/hold", loanHandler.PlaceHold)
+		r.With(mw.AdminOnly(logger)).Delete("/{loanID}/hold", loanHandler.ReleaseHold)
+		r.With(mw.AdminOnly(logger)).Post("/{loanID}/rate", loanHandler.RepriceLoan)
+		r.With(mw.AdminOnly(logger)).Post("/{loanID}/adjustments", loanHandler.ApplyScheduleAdjustment)
+		r.With(mw.AdminOnly(logger)).Delete("/{loanID}/adjustments/{adjustmentID}", loanHandler.RemoveScheduleAdjustment)
+		r.Get("/{loanID}/history", reportHandler.GetLoanHistory)
+		r.Get("/{loanID}/agreement", agreementHandler.GetAgreement)
+		r.Post("/{loanID}/agreement", agreementHandler.SendAgreement)
+		r.Post("/{loanID}/disbursement", agreementHandler.Disburse)
+		mountDocumentRoutes(r, "/{loanID}", documentHandler)
+		mountNoteRoutes(r, "/{loanID}", noteHandler)
+	})
+}
+
+// setupESignRoutes serves the e-signature provider's callbacks. They carry
+// no bearer token; the agreement service checks the provider's signature.
+func setupESignRoutes(router *chi.Mux, h *handler.AgreementHandler) {
+	router.Post("/esign/callback", h.HandleCallback)
+}
+
+func setupReportRoutes(router *chi.Mux, h *handler.ReportHandler, cfg *config.Config, logger *slog.Logger) {
+	router.Route("/reports", func(r chi.Router) {
+		r.Use(mw.AuthMiddleware(cfg.Server.Auth, logger))
+		r.Use(mw.StaffOnly(logger))
+		r.Get("/portfolio", h.GetPortfolio)
+		r.Get("/collections-by-channel", h.GetCollectionsByChannel)
+		r.Get("/tax", h.GetTaxReport)
 	})
 }
 
-func setupCustomerRoutes(r chi.Router, cfg *config.Config, svc customer.CustomerService, logger *slog.Logger) {
+func setupCustomerRoutes(r chi.Router, cfg *config.Config, svc customer.CustomerService, importSvc customer.ImportService, bulk *handler.BulkRunner, noteHandler *handler.NoteHandler, documentHandler *handler.DocumentHandler, directDebitHandler *handler.DirectDebitHandler, contactHandler *handler.ContactHandler, summaryHandler *handler.CustomerSummaryHandler, overviewHandler *handler.CustomerOverviewHandler, logger *slog.Logger) {
 	h := handler.NewCustomerHandler(svc, logger)
+	importHandler := handler.NewCustomerImportHandler(importSvc, bulk, cfg.Import.MaxRows, cfg.Import.MaxBytes, logger)
 
 	r.Route("/customers", func(r chi.Router) {
 		r.Use(mw.AuthMiddleware(cfg.Server.Auth, logger))
+		r.Use(mw.StaffOnly(logger))
 		r.Post("/", h.CreateCustomer)
 		r.Get("/", h.ListCustomers)
 		r.Get("/", h.FindCustomerByLoan)
+		r.Post("/import", importHandler.ImportCustomers)
 		r.Route("/{customerID}", func(r chi.Router) {
 			r.Get("/", h.GetCustomer)
 			r.Delete("/", h.DeactivateCustomer)
@@ -99,6 +263,154 @@ func setupCustomerRoutes(r chi.Router, cfg *config.Config, svc customer.Customer
 			r.Put("/loan", h.AssignLoanToCustomer)
 			r.Put("/delinquency", h.UpdateDelinquency)
 			r.Put("/reactivate", h.ReactivateCustomer)
+			r.Get("/preferences", h.GetPreferences)
+			r.Put("/preferences", h.UpdatePreferences)
+			r.With(mw.AdminOnly(logger)).Put("/risk-score", h.UpdateRiskScore)
+			r.With(mw.AdminOnly(logger)).Post("/merge", h.MergeCustomer)
+			r.Get("/summary", summaryHandler.GetSummary)
+			r.Get("/overview", overviewHandler.GetOverview)
+			r.Get("/contacts", contactHandler.ListContacts)
+			r.Post("/contacts", contactHandler.CreateContact)
+			r.Put("/contacts/{contactID}", contactHandler.UpdateContact)
+			r.Delete("/contacts/{contactID}", contactHandler.DeleteContact)
+			r.Post("/contacts/{contactID}/verification", contactHandler.SendVerification)
+			r.Post("/contacts/{contactID}/verification/confirm", contactHandler.ConfirmVerification)
+			r.Post("/mandates", directDebitHandler.CreateMandate)
+			r.Get("/mandates", directDebitHandler.ListMandates)
+			r.Delete("/mandates/{mandateID}", directDebitHandler.CancelMandate)
+			mountNoteRoutes(r, "", noteHandler)
+			mountDocumentRoutes(r, "", documentHandler)
+		})
+	})
+}
+
+func setupDirectDebitRoutes(router *chi.Mux, h *handler.DirectDebitHandler, cfg *config.Config, logger *slog.Logger) {
+	router.Route("/direct-debit", func(r chi.Router) {
+		r.Use(mw.AuthMiddleware(cfg.Server.Auth, logger))
+		r.Use(mw.StaffOnly(logger))
+		r.Post("/results", h.ProcessResults)
+	})
+}
+
+func setupJobRoutes(router *chi.Mux, runner *jobs.Runner, cfg *config.Config, logger *slog.Logger) {
+	h := handler.NewJobHandler(runner, logger)
+
+	router.Route("/jobs", func(r chi.Router) {
+		r.Use(mw.AuthMiddleware(cfg.Server.Auth, logger))
+		r.Use(mw.StaffOnly(logger))
+		r.Get("/", h.ListJobs)
+		r.Get("/{jobID}", h.GetJob)
+	})
+}
+
+func setupCollectionsRoutes(router *chi.Mux, svc collections.Service, cfg *config.Config, logger *slog.Logger) {
+	h := handler.NewCollectionsHandler(svc, logger)
+
+	router.Route("/collections", func(r chi.Router) {
+		r.Use(mw.AuthMiddleware(cfg.Server.Auth, logger))
+		r.Use(mw.StaffOnly(logger))
+		r.Get("/queue", h.GetQueue)
+		r.Post("/rules", h.CreateRule)
+		r.Get("/rules", h.ListRules)
+		r.Delete("/rules/{ruleID}", h.DeleteRule)
+		r.Get("/escalation-steps", h.ListEscalationSteps)
+		r.With(mw.AdminOnly(logger)).Put("/escalation-steps", h.ReplaceEscalationSteps)
+		r.Route("/assignments/{assignmentID}", func(r chi.Router) {
+			r.Post("/actions", h.RecordAction)
+			r.Get("/actions", h.ListActions)
+			r.Put("/collector", h.Reassign)
+		})
+	})
+}
+
+// mountNoteRoutes adds the notes and attachments routes below a loan or
+// customer route; prefix holds the path up to the subject ID parameter.
+func mountNoteRoutes(r chi.Router, prefix string, h *handler.NoteHandler) {
+	r.Post(prefix+"/notes", h.CreateNote)
+	r.Get(prefix+"/notes", h.ListNotes)
+	r.Delete(prefix+"/notes/{noteID}", h.DeleteNote)
+	r.Post(prefix+"/attachments", h.CreateAttachment)
+	r.Get(prefix+"/attachments", h.ListAttachments)
+	r.Delete(prefix+"/attachments/{attachmentID}", h.DeleteAttachment)
+}
+
+// mountDocumentRoutes adds the documents routes below a loan or customer.
+func mountDocumentRoutes(r chi.Router, prefix string, h *handler.DocumentHandler) {
+	r.Get(prefix+"/documents", h.ListDocuments)
+	r.Post(prefix+"/documents", h.RequestUpload)
+	r.Get(prefix+"/documents/{documentID}", h.DownloadDocument)
+	r.Delete(prefix+"/documents/{documentID}", h.DeleteDocument)
+	r.Post(prefix+"/documents/{documentID}/confirm", h.ConfirmUpload)
+}
+
+func setupSelfServiceRoutes(router *chi.Mux, loanService loan.LoanService, customerService customer.CustomerService, cfg *config.Config, logger *slog.Logger) {
+	h := handler.NewSelfServiceHandler(loanService, customerService, logger)
+
+	router.Route("/me", func(r chi.Router) {
+		r.Use(mw.AuthMiddleware(cfg.Server.Auth, logger))
+		r.Use(mw.CustomerScope(logger))
+		r.Get("/loans", h.MyLoans)
+		r.Get("/schedule", h.MySchedule)
+		r.Get("/outstanding", h.MyOutstanding)
+	})
+}
+
+func setupGraphQLRoutes(router *chi.Mux, loanService loan.LoanService, customerService customer.CustomerService, cfg *config.Config, logger *slog.Logger) {
+	h := handler.NewGraphQLHandler(graphql.NewBillingSchema(loanService, customerService), logger)
+
+	router.Route("/graphql", func(r chi.Router) {
+		r.Use(mw.AuthMiddleware(cfg.Server.Auth, logger))
+		r.Use(mw.StaffOnly(logger))
+		r.Post("/", h.Query)
+	})
+}
+
+func setupEventStreamRoutes(router *chi.Mux, hub *event.Hub, cfg *config.Config, logger *slog.Logger) {
+	h := handler.NewEventStreamHandler(hub, cfg.Events.HeartbeatInterval, logger)
+
+	router.Route("/events", func(r chi.Router) {
+		r.Use(mw.TokenFromQuery("access_token"))
+		r.Use(mw.AuthMiddleware(cfg.Server.Auth, logger))
+		r.Use(mw.StaffOnly(logger))
+		r.Get("/stream", h.Stream)
+	})
+}
+
+func setupAdminRoutes(router *chi.Mux, loanService loan.LoanService, integrityService integrity.Service, tenantService tenant.Service, sandboxService sandbox.Service, replayService event.ReplayService, runRecorder *jobs.RunRecorder, bulk *handler.BulkRunner, sloTracker *monitoring.SLOTracker, deprecationTracker *monitoring.DeprecationTracker, rateLimiter *mw.RateLimiterMiddleware, accessList *ratelimit.AccessList, cfg *config.Config, logger *slog.Logger) {
+	h := handler.NewSandboxHandler(sandboxService, logger)
+	loanHandler := handler.NewLoanHandler(loanService, logger)
+	replayHandler := handler.NewEventReplayHandler(replayService, bulk, logger)
+	repricingHandler := handler.NewLoanRepricingHandler(loanService, bulk, logger)
+	sloHandler := handler.NewSLOHandler(sloTracker, logger)
+	deprecationHandler := handler.NewDeprecationHandler(deprecationTracker, logger)
+	rateLimitHandler := handler.NewRateLimitHandler(rateLimiter, accessList, logger)
+	integrityHandler := handler.NewIntegrityHandler(integrityService, logger)
+	jobRunHandler := handler.NewJobRunHandler(runRecorder, logger)
+	tenantHandler := handler.NewTenantHandler(tenantService, logger)
+
+	router.Route("/admin", func(r chi.Router) {
+		r.Use(mw.AuthMiddleware(cfg.Server.Auth, logger))
+		r.Use(mw.AdminOnly(logger))
+		r.Get("/sandbox/clock", h.GetClock)
+		r.Post("/sandbox/clock/advance", h.AdvanceClock)
+		r.Get("/events", replayHandler.ListEvents)
+		r.Post("/events/replay", replayHandler.ReplayEvents)
+		r.Get("/slo", sloHandler.GetSummary)
+		r.Get("/deprecations", deprecationHandler.ListUsage)
+		r.Post("/loans/{loanID}/schedule/rebuild", loanHandler.RebuildSchedule)
+		r.Post("/loans/repricing", repricingHandler.RepriceLoans)
+		r.Get("/integrity/findings", integrityHandler.ListFindings)
+		r.Get("/jobs/{name}/runs", jobRunHandler.ListRuns)
+		r.Get("/jobs/{name}/runs/{runID}", jobRunHandler.GetRun)
+		r.Get("/tenants/{tenantID}/settings", tenantHandler.GetSettings)
+		r.Put("/tenants/{tenantID}/settings", tenantHandler.UpdateSettings)
+		r.Route("/ratelimit", func(r chi.Router) {
+			r.Get("/consumers", rateLimitHandler.TopConsumers)
+			r.Get("/lists", rateLimitHandler.GetLists)
+			r.Put("/blocklist/{principal}", rateLimitHandler.AddToBlocklist)
+			r.Delete("/blocklist/{principal}", rateLimitHandler.RemoveFromBlocklist)
+			r.Put("/allowlist/{principal}", rateLimitHandler.AddToAllowlist)
+			r.Delete("/allowlist/{principal}", rateLimitHandler.RemoveFromAllowlist)
 		})
 	})
 }
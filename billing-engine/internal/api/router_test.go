@@ -0,0 +1,164 @@
+package api
+
+import (
+	mw "billing-engine/internal/api/middleware"
+	"billing-engine/internal/api/openapi"
+	"billing-engine/internal/config"
+	"billing-engine/internal/domain/agreement"
+	"billing-engine/internal/domain/collections"
+	"billing-engine/internal/domain/contact"
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/domain/directdebit"
+	"billing-engine/internal/domain/document"
+	"billing-engine/internal/domain/integrity"
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/domain/note"
+	"billing-engine/internal/domain/overview"
+	"billing-engine/internal/domain/summary"
+	"billing-engine/internal/domain/tenant"
+	"billing-engine/internal/event"
+	"billing-engine/internal/pkg/buildinfo"
+	"billing-engine/internal/pkg/clock"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubLoanService struct{ loan.LoanService }
+
+type stubCustomerService struct{ customer.CustomerService }
+
+type stubImportService struct{ customer.ImportService }
+
+type stubNoteService struct{ note.Service }
+
+type stubDocumentService struct{ document.Service }
+
+type stubAgreementService struct{ agreement.Service }
+
+type stubSnapshotService struct{ loan.SnapshotService }
+
+type stubDirectDebitService struct{ directdebit.Service }
+type stubContactService struct{ contact.Service }
+type stubCollectionsService struct{ collections.Service }
+type stubSummaryService struct{ summary.Service }
+type stubOverviewService struct{ overview.Service }
+type stubIntegrityService struct{ integrity.Service }
+type stubTenantService struct{ tenant.Service }
+type stubReplayService struct{ event.ReplayService }
+
+var undocumentedRoutes = map[string]bool{
+	"/health":       true,
+	"/version":      true,
+	"/metrics":      true,
+	"/swagger":      true,
+	"/swagger/*":    true,
+	"/openapi.json": true,
+}
+
+func TestOpenAPIDocumentsAllRoutes(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Metrics.Path = "/metrics"
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	router := SetupRouter(stubLoanService{}, stubCustomerService{}, stubImportService{}, stubNoteService{}, stubDocumentService{}, stubAgreementService{}, stubSnapshotService{}, stubDirectDebitService{}, stubContactService{}, stubCollectionsService{}, stubSummaryService{}, stubOverviewService{}, stubIntegrityService{}, stubTenantService{}, event.NewHub(1, 0, logger), stubReplayService{}, clock.System(), nil, nil, nil, nil, nil, cfg, logger)
+
+	documented := map[string]bool{}
+	for _, route := range openapi.Routes() {
+		documented[route.Method+" "+openapi.PathPrefix+route.Path] = true
+	}
+
+	mounted := map[string]bool{}
+	err := chi.Walk(router, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		route = strings.ReplaceAll(route, "/*/", "/")
+		if route != "/" {
+			route = strings.TrimSuffix(route, "/")
+		}
+		if undocumentedRoutes[route] {
+			return nil
+		}
+		mounted[method+" "+route] = true
+		assert.True(t, documented[method+" "+route], "route %s %s is missing from the OpenAPI route table", method, route)
+		return nil
+	})
+	require.NoError(t, err)
+
+	for key := range documented {
+		assert.True(t, mounted[key], "documented route %s is not mounted", key)
+	}
+}
+
+func TestUploadRoutesAreMounted(t *testing.T) {
+	cfg := &config.Config{}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	router := SetupRouter(stubLoanService{}, stubCustomerService{}, stubImportService{}, stubNoteService{}, stubDocumentService{}, stubAgreementService{}, stubSnapshotService{}, stubDirectDebitService{}, stubContactService{}, stubCollectionsService{}, stubSummaryService{}, stubOverviewService{}, stubIntegrityService{}, stubTenantService{}, event.NewHub(1, 0, logger), stubReplayService{}, clock.System(), nil, nil, nil, nil, nil, cfg, logger)
+
+	mounted := map[string]bool{}
+	err := chi.Walk(router, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		mounted[method+" "+mw.UnversionedPattern(strings.TrimSuffix(strings.ReplaceAll(route, "/*/", "/"), "/"))] = true
+		return nil
+	})
+	require.NoError(t, err)
+
+	for _, route := range uploadRoutes {
+		assert.True(t, mounted[route], "upload route %s is exempt from the body limit but not mounted", route)
+	}
+}
+
+func TestAPIVersionRoutes(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	serve := func(api config.APIConfig, path string) *httptest.ResponseRecorder {
+		cfg := &config.Config{}
+		cfg.Server.API = api
+		router := SetupRouter(stubLoanService{}, stubCustomerService{}, stubImportService{}, stubNoteService{}, stubDocumentService{}, stubAgreementService{}, stubSnapshotService{}, stubDirectDebitService{}, stubContactService{}, stubCollectionsService{}, stubSummaryService{}, stubOverviewService{}, stubIntegrityService{}, stubTenantService{}, event.NewHub(1, 0, logger), stubReplayService{}, clock.System(), nil, nil, nil, nil, nil, cfg, logger)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, strings.NewReader("{")))
+		return rec
+	}
+	legacy := config.APIConfig{LegacyRoutes: true, LegacySunset: "2026-06-30"}
+
+	rec := serve(legacy, "/v1/auth/token")
+	assert.NotEqual(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, "v1", rec.Header().Get(mw.APIVersionHeader))
+	assert.Empty(t, rec.Header().Get("Deprecation"))
+
+	rec = serve(legacy, "/auth/token")
+	assert.NotEqual(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, "v1", rec.Header().Get(mw.APIVersionHeader))
+	assert.Equal(t, "true", rec.Header().Get("Deprecation"))
+	assert.Equal(t, `</v1/auth/token>; rel="successor-version"`, rec.Header().Get("Link"))
+	assert.Equal(t, "Tue, 30 Jun 2026 00:00:00 GMT", rec.Header().Get("Sunset"))
+
+	rec = serve(config.APIConfig{}, "/auth/token")
+	assert.Equal(t, http.StatusNotFound, rec.Code, "the unprefixed routes are gone once the legacy routes are turned off")
+
+	rec = serve(config.APIConfig{DeprecatedRoutes: map[string]string{"post /auth/token": ""}}, "/v1/auth/token")
+	assert.Equal(t, "true", rec.Header().Get("Deprecation"), "routes can be deprecated in every version")
+}
+
+func TestBuildEndpoints(t *testing.T) {
+	router := chi.NewRouter()
+	setupBuildEndpoints(router)
+	build := buildinfo.Get()
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var health map[string]string
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&health))
+	assert.Equal(t, map[string]string{"status": "ok", "version": build.Version, "commit": build.Commit}, health)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/version", nil))
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	var version buildinfo.Info
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&version))
+	assert.Equal(t, build, version)
+}
@@ -0,0 +1,48 @@
+package batch
+
+import (
+	"billing-engine/internal/domain/collections"
+	"billing-engine/internal/jobs"
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// CollectionsAssignmentJob routes past-due loans into collector queues. It
+// reads the days past due the delinquency job stores, so it is meant to run
+// after it.
+type CollectionsAssignmentJob struct {
+	collectionsService collections.Service
+	logger             *slog.Logger
+}
+
+func NewCollectionsAssignmentJob(collectionsSvc collections.Service, logger *slog.Logger) *CollectionsAssignmentJob {
+	if collectionsSvc == nil || logger == nil {
+		panic("CollectionsAssignmentJob dependencies cannot be nil")
+	}
+	return &CollectionsAssignmentJob{
+		collectionsService: collectionsSvc,
+		logger:             logger.With("job", "CollectionsAssignment"),
+	}
+}
+
+func (j *CollectionsAssignmentJob) Run(ctx context.Context) error {
+	startTime := time.Now()
+	j.logger.InfoContext(ctx, "Starting daily collections assignment job.")
+
+	report, err := j.collectionsService.Assign(ctx)
+	if err != nil {
+		j.logger.ErrorContext(ctx, "Collections assignment job failed.", slog.Any("error", err))
+		return fmt.Errorf("collections assignment job failed: %w", err)
+	}
+
+	jobs.ProgressFrom(ctx).Done(report.Resolved + report.Assigned + report.Unmatched)
+	j.logger.InfoContext(ctx, "Collections assignment job finished.",
+		slog.Int("resolved", report.Resolved),
+		slog.Int("assigned", report.Assigned),
+		slog.Int("unmatched", report.Unmatched),
+		slog.Int("open", report.Open),
+		slog.Duration("duration", time.Since(startTime)))
+	return nil
+}
@@ -0,0 +1,112 @@
+package batch_test
+
+import (
+	"billing-engine/internal/batch"
+	"billing-engine/internal/domain/collections"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockCollectionsService struct {
+	mock.Mock
+}
+
+func (m *MockCollectionsService) CreateRule(ctx context.Context, rule *collections.Rule) (*collections.Rule, error) {
+	args := m.Called(ctx, rule)
+	created, _ := args.Get(0).(*collections.Rule)
+	return created, args.Error(1)
+}
+
+func (m *MockCollectionsService) ListRules(ctx context.Context) ([]collections.Rule, error) {
+	args := m.Called(ctx)
+	rules, _ := args.Get(0).([]collections.Rule)
+	return rules, args.Error(1)
+}
+
+func (m *MockCollectionsService) DeleteRule(ctx context.Context, ruleID int64) error {
+	return m.Called(ctx, ruleID).Error(0)
+}
+
+func (m *MockCollectionsService) GetOpenAssignment(ctx context.Context, loanID int64) (*collections.Assignment, error) {
+	args := m.Called(ctx, loanID)
+	assignment, _ := args.Get(0).(*collections.Assignment)
+	return assignment, args.Error(1)
+}
+
+func (m *MockCollectionsService) ListQueue(ctx context.Context, collector string) ([]collections.Assignment, error) {
+	args := m.Called(ctx, collector)
+	queue, _ := args.Get(0).([]collections.Assignment)
+	return queue, args.Error(1)
+}
+
+func (m *MockCollectionsService) RecordAction(ctx context.Context, assignmentID int64, collector string, action *collections.Action) (*collections.Action, error) {
+	args := m.Called(ctx, assignmentID, collector, action)
+	recorded, _ := args.Get(0).(*collections.Action)
+	return recorded, args.Error(1)
+}
+
+func (m *MockCollectionsService) ListActions(ctx context.Context, assignmentID int64) ([]collections.Action, error) {
+	args := m.Called(ctx, assignmentID)
+	actions, _ := args.Get(0).([]collections.Action)
+	return actions, args.Error(1)
+}
+
+func (m *MockCollectionsService) Reassign(ctx context.Context, assignmentID int64, collector, by, reason string) (*collections.Assignment, error) {
+	args := m.Called(ctx, assignmentID, collector, by, reason)
+	assignment, _ := args.Get(0).(*collections.Assignment)
+	return assignment, args.Error(1)
+}
+
+func (m *MockCollectionsService) Assign(ctx context.Context) (*collections.RunReport, error) {
+	args := m.Called(ctx)
+	report, _ := args.Get(0).(*collections.RunReport)
+	return report, args.Error(1)
+}
+
+func (m *MockCollectionsService) ListEscalationSteps(ctx context.Context) ([]collections.EscalationStep, error) {
+	args := m.Called(ctx)
+	steps, _ := args.Get(0).([]collections.EscalationStep)
+	return steps, args.Error(1)
+}
+
+func (m *MockCollectionsService) ReplaceEscalationSteps(ctx context.Context, steps []collections.EscalationStep) ([]collections.EscalationStep, error) {
+	args := m.Called(ctx, steps)
+	ladder, _ := args.Get(0).([]collections.EscalationStep)
+	return ladder, args.Error(1)
+}
+
+func (m *MockCollectionsService) Escalate(ctx context.Context) (*collections.EscalationReport, error) {
+	args := m.Called(ctx)
+	report, _ := args.Get(0).(*collections.EscalationReport)
+	return report, args.Error(1)
+}
+
+func TestCollectionsAssignmentJobRun(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	t.Run("assigns the past-due book", func(t *testing.T) {
+		service := new(MockCollectionsService)
+		service.On("Assign", ctx).Return(&collections.RunReport{Resolved: 1, Assigned: 2, Open: 5}, nil)
+
+		err := batch.NewCollectionsAssignmentJob(service, logger).Run(ctx)
+
+		assert.NoError(t, err)
+		service.AssertExpectations(t)
+	})
+
+	t.Run("returns service error", func(t *testing.T) {
+		service := new(MockCollectionsService)
+		service.On("Assign", ctx).Return(nil, errors.New("database error"))
+
+		err := batch.NewCollectionsAssignmentJob(service, logger).Run(ctx)
+
+		assert.ErrorContains(t, err, "database error")
+	})
+}
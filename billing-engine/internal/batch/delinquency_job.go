@@ -3,11 +3,17 @@ package batch
 import (
 	"billing-engine/internal/domain/customer"
 	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/jobs"
 	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"billing-engine/internal/pkg/scope"
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
+	"slices"
 	"sync"
 	"time"
 )
@@ -16,22 +22,35 @@ type UpdateDelinquencyJob struct {
 	loanRepo        loan.Repository
 	loanService     loan.LoanService
 	customerService customer.CustomerService
+	policy          loan.DelinquencyPolicySource
+	clock           clock.Clock
 	logger          *slog.Logger
 }
 
+// NewUpdateDelinquencyJob builds the job that stores every active loan's days
+// past due and keeps the customers' delinquency flags in line with policy,
+// read for the tenant of each loan. Delinquent customers are sent a notice
+// when they are flagged and when their loan moves to another DPD bucket, not
+// on every run. Where the policy has a late fee, loans that are not on hold
+// are charged it for each installment past its grace period.
+// clk decides which installments are past due; nil means the wall clock.
 func NewUpdateDelinquencyJob(
 	loanRepo loan.Repository,
 	loanSvc loan.LoanService,
 	customerSvc customer.CustomerService,
+	policy loan.DelinquencyPolicySource,
+	clk clock.Clock,
 	logger *slog.Logger,
 ) *UpdateDelinquencyJob {
-	if loanRepo == nil || loanSvc == nil || customerSvc == nil || logger == nil {
+	if loanRepo == nil || loanSvc == nil || customerSvc == nil || policy == nil || logger == nil {
 		panic("UpdateDelinquencyJob dependencies cannot be nil")
 	}
 	return &UpdateDelinquencyJob{
 		loanRepo:        loanRepo,
 		loanService:     loanSvc,
 		customerService: customerSvc,
+		policy:          policy,
+		clock:           clock.OrSystem(clk),
 		logger:          logger.With("job", "UpdateDelinquency"),
 	}
 }
@@ -40,13 +59,16 @@ func (j *UpdateDelinquencyJob) Run(ctx context.Context) error {
 	startTime := time.Now()
 	j.logger.InfoContext(ctx, "Starting daily customer delinquency update job.")
 
-	j.logger.DebugContext(ctx, "Fetching active loan IDs from repository.")
-	activeLoanIDs, err := j.loanRepo.GetAllActiveLoanIDs(ctx)
+	j.logger.DebugContext(ctx, "Fetching active loans from repository.")
+	activeLoans, err := j.loanRepo.GetActiveLoanTenants(ctx)
 	if err != nil {
-		j.logger.ErrorContext(ctx, "Failed to get active loan IDs, aborting job.", slog.Any("error", err))
+		j.logger.ErrorContext(ctx, "Failed to get active loans, aborting job.", slog.Any("error", err))
 		return fmt.Errorf("cannot run job, failed to get active loans: %w", err)
 	}
+	activeLoanIDs := slices.Sorted(maps.Keys(activeLoans))
 	j.logger.InfoContext(ctx, "Fetched active loan IDs.", slog.Int("count", len(activeLoanIDs)))
+	progress := jobs.ProgressFrom(ctx)
+	progress.SetTotal(len(activeLoanIDs))
 
 	if len(activeLoanIDs) == 0 {
 		j.logger.InfoContext(ctx, "No active loans found to process.")
@@ -54,70 +76,172 @@ func (j *UpdateDelinquencyJob) Run(ctx context.Context) error {
 		return nil
 	}
 
+	// Every loan is judged as of the same instant and by the same policy of
+	// its tenant, even if the run crosses midnight or the settings change
+	// meanwhile.
+	now := j.clock.Now()
+	policies := make(map[string]loan.DelinquencyPolicy)
+	for _, tenantID := range activeLoans {
+		if _, ok := policies[tenantID]; ok {
+			continue
+		}
+		policy, err := j.policy.Current(scope.WithTenant(ctx, tenantID))
+		if err != nil {
+			j.logger.ErrorContext(ctx, "Failed to read the delinquency policy, aborting job.", slog.String("tenantID", tenantID), slog.Any("error", err))
+			return fmt.Errorf("cannot run job, failed to read the delinquency policy of tenant %q: %w", tenantID, err)
+		}
+		policies[tenantID] = policy
+	}
 	var wg sync.WaitGroup
-	var processedCount, delinquentCount, updatedToDelinquent, updatedToNotDelinquent, errorCount int32
+	// Changed flags are written together once every loan has been checked.
+	var pendingMu sync.Mutex
+	var pending []customer.CustomerDelinquency
+	// The customers' notice state follows the flags they end up with.
+	var notices []customer.DelinquencyNotice
+	var cured []int64
+	var processedCount, delinquentCount, updatedToDelinquent, updatedToNotDelinquent, noticesSent, lateFeesPosted, errorCount int32
 
 	for _, loanID := range activeLoanIDs {
 		wg.Add(1)
 		go func(currentLoanID int64) {
 			defer wg.Done()
+			// A loan counts as failed on the first error it hit, even when
+			// the rest of its check went on.
+			var loanErr error
+			defer func() {
+				if loanErr != nil {
+					progress.Fail(fmt.Errorf("loan %d: %w", currentLoanID, loanErr))
+				} else {
+					progress.Done(1)
+				}
+			}()
 
 			logCtx := j.logger.With(slog.Int64("loanID", currentLoanID))
-			isDelinquent := false
-			var checkErr error
+			policy := policies[activeLoans[currentLoanID]]
 
 			logCtx.DebugContext(ctx, "Checking loan delinquency status.")
-			isDelinquent, checkErr = j.loanService.IsDelinquent(ctx, currentLoanID)
+			schedule, checkErr := j.loanService.GetLoanSchedule(ctx, currentLoanID)
 			if checkErr != nil {
 				if errors.Is(checkErr, apperrors.ErrNotFound) {
 					logCtx.WarnContext(ctx, "Loan not found during delinquency check (potentially deleted recently?)", slog.Any("error", checkErr))
 				} else {
 					logCtx.ErrorContext(ctx, "Failed to check loan delinquency", slog.Any("error", checkErr))
 					errorCount++
+					loanErr = checkErr
 				}
 				return
 			}
 
-			if isDelinquent {
-				delinquentCount++
+			daysPastDue := loan.DaysPastDue(schedule, now)
+			if err := j.loanRepo.UpdateDaysPastDue(ctx, currentLoanID, daysPastDue); err != nil {
+				logCtx.ErrorContext(ctx, "Failed to update loan days past due", slog.Int("days_past_due", daysPastDue), slog.Any("error", err))
+				errorCount++
+				loanErr = err
+			}
+
+			if policy.LateFee > 0 {
+				fees, feeErr := j.loanService.ChargeLateFees(ctx, currentLoanID, policy)
+				switch {
+				case errors.Is(feeErr, apperrors.ErrLoanOnHold):
+					logCtx.InfoContext(ctx, "Loan is on hold, no late fee charged.")
+				case feeErr != nil:
+					logCtx.ErrorContext(ctx, "Failed to charge late fees", slog.Any("error", feeErr))
+					errorCount++
+					loanErr = cmp.Or(loanErr, feeErr)
+				}
+				pendingMu.Lock()
+				lateFeesPosted += int32(len(fees))
+				pendingMu.Unlock()
 			}
 
 			logCtx.DebugContext(ctx, "Finding customer associated with loan.")
 			cust, custErr := j.customerService.FindCustomerByLoan(ctx, currentLoanID)
 			if custErr != nil {
-				if errors.Is(custErr, customer.ErrNotFound) || errors.Is(custErr, apperrors.ErrNotFound) {
+				if errors.Is(custErr, apperrors.ErrNotFound) {
 					logCtx.WarnContext(ctx, "No customer found linked to this loan (data inconsistency?)", slog.Any("error", custErr))
 				} else {
 					logCtx.ErrorContext(ctx, "Failed to find customer by loan", slog.Any("error", custErr))
 					errorCount++
+					loanErr = cmp.Or(loanErr, custErr)
 				}
 				return
 			}
 			logCtx = logCtx.With(slog.Int64("customerID", cust.CustomerID))
 
+			isDelinquent := policy.Evaluate(schedule, now, cust.IsDelinquent)
+			if isDelinquent {
+				delinquentCount++
+			}
+
+			pendingMu.Lock()
 			if cust.IsDelinquent != isDelinquent {
-				logCtx.InfoContext(ctx, "Updating customer delinquency status.", slog.Bool("new_status", isDelinquent))
-				updateErr := j.customerService.UpdateDelinquency(ctx, cust.CustomerID, isDelinquent)
-				if updateErr != nil {
-					logCtx.ErrorContext(ctx, "Failed to update customer delinquency status", slog.Any("error", updateErr))
-					errorCount++
-				} else {
-					logCtx.InfoContext(ctx, "Customer delinquency status updated successfully.", slog.Bool("status", isDelinquent))
-					if isDelinquent {
-						updatedToDelinquent++
-					} else {
-						updatedToNotDelinquent++
-					}
-				}
+				logCtx.InfoContext(ctx, "Queueing customer delinquency status update.", slog.Bool("new_status", isDelinquent))
+				pending = append(pending, customer.CustomerDelinquency{CustomerID: cust.CustomerID, IsDelinquent: isDelinquent})
 			} else {
 				logCtx.DebugContext(ctx, "Customer delinquency status already correct.", slog.Bool("status", isDelinquent))
 			}
+			if isDelinquent {
+				notices = append(notices, customer.DelinquencyNotice{
+					CustomerID:  cust.CustomerID,
+					LoanID:      currentLoanID,
+					DaysPastDue: daysPastDue,
+					Bucket:      loan.DPDBucket(daysPastDue),
+				})
+			} else {
+				cured = append(cured, cust.CustomerID)
+			}
+			pendingMu.Unlock()
 			processedCount++
 
 		}(loanID)
 	}
 
 	wg.Wait()
+
+	if len(pending) > 0 {
+		// Updating in ID order keeps the row locks in the same order as any
+		// other run.
+		slices.SortFunc(pending, func(a, b customer.CustomerDelinquency) int { return cmp.Compare(a.CustomerID, b.CustomerID) })
+		j.logger.InfoContext(ctx, "Updating customer delinquency statuses.", slog.Int("count", len(pending)))
+		changed, updateErr := j.customerService.UpdateDelinquencyBulk(ctx, pending)
+		if updateErr != nil {
+			j.logger.ErrorContext(ctx, "Failed to update customer delinquency statuses", slog.Any("error", updateErr))
+			errorCount++
+			// The flags did not change, so neither does the notice state
+			// of those customers; the next run tries both again.
+			flipped := make(map[int64]bool, len(pending))
+			for _, p := range pending {
+				flipped[p.CustomerID] = true
+			}
+			notices = slices.DeleteFunc(notices, func(n customer.DelinquencyNotice) bool { return flipped[n.CustomerID] })
+			cured = slices.DeleteFunc(cured, func(id int64) bool { return flipped[id] })
+		}
+		for _, cust := range changed {
+			if cust.IsDelinquent {
+				updatedToDelinquent++
+			} else {
+				updatedToNotDelinquent++
+			}
+		}
+	}
+
+	if len(notices) > 0 {
+		slices.SortFunc(notices, func(a, b customer.DelinquencyNotice) int { return cmp.Compare(a.CustomerID, b.CustomerID) })
+		sent, noticeErr := j.customerService.NotifyDelinquency(ctx, notices)
+		if noticeErr != nil {
+			j.logger.ErrorContext(ctx, "Failed to send delinquency notices", slog.Any("error", noticeErr))
+			errorCount++
+		}
+		noticesSent = int32(len(sent))
+	}
+	if len(cured) > 0 {
+		slices.Sort(cured)
+		if clearErr := j.customerService.ClearDelinquencyNotices(ctx, cured); clearErr != nil {
+			j.logger.ErrorContext(ctx, "Failed to clear delinquency notices", slog.Any("error", clearErr))
+			errorCount++
+		}
+	}
+
 	duration := time.Since(startTime)
 	summaryLog := j.logger.With(
 		slog.Duration("duration", duration),
@@ -126,6 +250,8 @@ func (j *UpdateDelinquencyJob) Run(ctx context.Context) error {
 		slog.Int("loans_found_delinquent", int(delinquentCount)),
 		slog.Int("customers_updated_to_delinquent", int(updatedToDelinquent)),
 		slog.Int("customers_updated_to_not_delinquent", int(updatedToNotDelinquent)),
+		slog.Int("delinquency_notices_sent", int(noticesSent)),
+		slog.Int("late_fees_posted", int(lateFeesPosted)),
 		slog.Int("errors_encountered", int(errorCount)),
 	)
 	if errorCount > 0 {
@@ -4,7 +4,10 @@ import (
 	"billing-engine/internal/batch"
 	"billing-engine/internal/domain/customer"
 	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/jobs"
 	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"billing-engine/internal/pkg/scope"
 	"context"
 	"errors"
 	"fmt"
@@ -13,9 +16,10 @@ import (
 	"testing"
 	"time"
 
-	"github.com/jackc/pgx/v5"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 type MockLoanService struct {
@@ -30,27 +34,178 @@ func (m *MockLoanService) GetLoanSchedule(ctx context.Context, loanID int64) ([]
 	return nil, args.Error(1)
 }
 
-func (m *MockLoanService) GetLoan(ctx context.Context, loanID int64) (*loan.Loan, error) {
+func (m *MockLoanService) PlaceHold(ctx context.Context, loanID int64, reason, placedBy string) (*loan.Hold, error) {
+	args := m.Called(ctx, loanID, reason, placedBy)
+	if hold, ok := args.Get(0).(*loan.Hold); ok {
+		return hold, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockLoanService) ReleaseHold(ctx context.Context, loanID int64, releasedBy string) (*loan.Hold, error) {
+	args := m.Called(ctx, loanID, releasedBy)
+	if hold, ok := args.Get(0).(*loan.Hold); ok {
+		return hold, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockLoanService) RebuildSchedule(ctx context.Context, loanID int64, dryRun bool) (*loan.ScheduleRebuild, error) {
+	args := m.Called(ctx, loanID, dryRun)
+	if rebuild, ok := args.Get(0).(*loan.ScheduleRebuild); ok {
+		return rebuild, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockLoanService) RepriceLoan(ctx context.Context, loanID int64, rate float64, effectiveFrom time.Time, changedBy string) (*loan.Repricing, error) {
+	args := m.Called(ctx, loanID, rate, effectiveFrom, changedBy)
+	if repricing, ok := args.Get(0).(*loan.Repricing); ok {
+		return repricing, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockLoanService) RepriceLoans(ctx context.Context, filter loan.RepricingFilter, rate float64, effectiveFrom time.Time, changedBy string) (*loan.RepricingReport, error) {
+	args := m.Called(ctx, filter, rate, effectiveFrom, changedBy)
+	if report, ok := args.Get(0).(*loan.RepricingReport); ok {
+		return report, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockLoanService) ApplyScheduleAdjustment(ctx context.Context, loanID int64, adjustment loan.ScheduleAdjustment, appliedBy string) (*loan.AdjustmentPlan, error) {
+	args := m.Called(ctx, loanID, adjustment, appliedBy)
+	if plan, ok := args.Get(0).(*loan.AdjustmentPlan); ok {
+		return plan, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockLoanService) RemoveScheduleAdjustment(ctx context.Context, loanID, adjustmentID int64, removedBy string) (*loan.AdjustmentPlan, error) {
+	args := m.Called(ctx, loanID, adjustmentID, removedBy)
+	if plan, ok := args.Get(0).(*loan.AdjustmentPlan); ok {
+		return plan, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockLoanService) Prepay(ctx context.Context, loanID int64, amount loan.Money, option loan.PrepaymentOption, details loan.PaymentDetails, recordedBy string) (*loan.Reamortization, error) {
+	args := m.Called(ctx, loanID, amount, option, details, recordedBy)
+	if plan, ok := args.Get(0).(*loan.Reamortization); ok {
+		return plan, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockLoanService) PreviewRestructure(ctx context.Context, loanID int64, r loan.Restructure) (*loan.RestructurePreview, error) {
+	args := m.Called(ctx, loanID, r)
+	if preview, ok := args.Get(0).(*loan.RestructurePreview); ok {
+		return preview, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockLoanService) ChargeLateFees(ctx context.Context, loanID int64, policy loan.DelinquencyPolicy) ([]loan.Fee, error) {
+	args := m.Called(ctx, loanID, policy)
+	if fees, ok := args.Get(0).([]loan.Fee); ok {
+		return fees, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockLoanService) PostFee(ctx context.Context, loanID int64, feeType loan.FeeType, amount loan.Money, reason, postedBy string) (*loan.Fee, error) {
+	args := m.Called(ctx, loanID, feeType, amount, reason, postedBy)
+	if fee, ok := args.Get(0).(*loan.Fee); ok {
+		return fee, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockLoanService) ListPayments(ctx context.Context, loanID int64, limit int) ([]loan.Payment, error) {
+	args := m.Called(ctx, loanID, limit)
+	payments, _ := args.Get(0).([]loan.Payment)
+	return payments, args.Error(1)
+}
+
+func (m *MockLoanService) SearchLoans(ctx context.Context, q loan.LoanSearch) ([]loan.SearchMatch, error) {
+	args := m.Called(ctx, q)
+	matches, _ := args.Get(0).([]loan.SearchMatch)
+	return matches, args.Error(1)
+}
+
+func (m *MockLoanService) GetFees(ctx context.Context, loanID int64) ([]loan.Fee, error) {
 	args := m.Called(ctx, loanID)
+	fees, _ := args.Get(0).([]loan.Fee)
+	return fees, args.Error(1)
+}
+
+func (m *MockLoanService) RequestFeeWaiver(ctx context.Context, loanID, feeID int64, reason, requestedBy string) (*loan.FeeWaiver, error) {
+	args := m.Called(ctx, loanID, feeID, reason, requestedBy)
+	if waiver, ok := args.Get(0).(*loan.FeeWaiver); ok {
+		return waiver, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockLoanService) DecideFeeWaiver(ctx context.Context, loanID, feeID int64, approve bool, decidedBy string) (*loan.FeeWaiver, error) {
+	args := m.Called(ctx, loanID, feeID, approve, decidedBy)
+	if waiver, ok := args.Get(0).(*loan.FeeWaiver); ok {
+		return waiver, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockLoanService) GetLoan(ctx context.Context, loanID int64, includeSchedule bool) (*loan.Loan, error) {
+	args := m.Called(ctx, loanID, includeSchedule)
 	if loan, ok := args.Get(0).(*loan.Loan); ok {
 		return loan, args.Error(1)
 	}
 	return nil, args.Error(1)
 }
 
-func (m *MockLoanService) CreateLoan(ctx context.Context, customerID int64, annualInterestRate loan.Money, termWeeks int, amount loan.Money, time time.Time) (*loan.Loan, error) {
-	args := m.Called(ctx, customerID, annualInterestRate, termWeeks, amount)
+func (m *MockLoanService) CreateLoan(ctx context.Context, customerID int64, annualInterestRate loan.Money, termWeeks int, amount loan.Money, time time.Time, externalRef string, publicID uuid.UUID, product string) (*loan.Loan, error) {
+	args := m.Called(ctx, customerID, annualInterestRate, termWeeks, amount, externalRef, publicID, product)
 	if createdLoan, ok := args.Get(0).(*loan.Loan); ok {
 		return createdLoan, args.Error(1)
 	}
 	return nil, args.Error(1)
 }
 
-func (m *MockLoanService) MakePayment(ctx context.Context, loanID int64, amount loan.Money) error {
-	args := m.Called(ctx, loanID, amount)
+func (m *MockLoanService) GetLoanByExternalRef(ctx context.Context, externalRef string, includeSchedule bool) (*loan.Loan, error) {
+	args := m.Called(ctx, externalRef, includeSchedule)
+	if found, ok := args.Get(0).(*loan.Loan); ok {
+		return found, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockLoanService) ResolveLoanID(ctx context.Context, publicID uuid.UUID) (int64, error) {
+	args := m.Called(ctx, publicID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockLoanService) MakePayment(ctx context.Context, loanID int64, amount loan.Money, details loan.PaymentDetails) error {
+	args := m.Called(ctx, loanID, amount, details)
 	return args.Error(0)
 }
 
+func (m *MockLoanService) CollectionsByChannel(ctx context.Context, from, to time.Time) (*loan.CollectionsReport, error) {
+	args := m.Called(ctx, from, to)
+	if report, ok := args.Get(0).(*loan.CollectionsReport); ok {
+		return report, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockLoanService) TaxReport(ctx context.Context, from, to time.Time) (*loan.TaxReport, error) {
+	args := m.Called(ctx, from, to)
+	if report, ok := args.Get(0).(*loan.TaxReport); ok {
+		return report, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
 func (m *MockLoanService) GetOutstanding(ctx context.Context, loanID int64) (loan.Money, error) {
 	args := m.Called(ctx, loanID)
 	if outstanding, ok := args.Get(0).(loan.Money); ok {
@@ -59,6 +214,12 @@ func (m *MockLoanService) GetOutstanding(ctx context.Context, loanID int64) (loa
 	return 0, args.Error(1)
 }
 
+func (m *MockLoanService) GetOutstandingBreakdown(ctx context.Context, loanID int64) (*loan.OutstandingBreakdown, error) {
+	args := m.Called(ctx, loanID)
+	breakdown, _ := args.Get(0).(*loan.OutstandingBreakdown)
+	return breakdown, args.Error(1)
+}
+
 func (m *MockLoanService) IsDelinquent(ctx context.Context, loanID int64) (bool, error) {
 	args := m.Called(ctx, loanID)
 	if isDelinquent, ok := args.Get(0).(bool); ok {
@@ -67,15 +228,28 @@ func (m *MockLoanService) IsDelinquent(ctx context.Context, loanID int64) (bool,
 	return false, args.Error(1)
 }
 
-type MockLoanRepository struct {
-	mock.Mock
+func (m *MockLoanService) GetLastModified(ctx context.Context, loanID int64) (time.Time, error) {
+	args := m.Called(ctx, loanID)
+	if lastModified, ok := args.Get(0).(time.Time); ok {
+		return lastModified, args.Error(1)
+	}
+	return time.Time{}, args.Error(1)
 }
 
-type TxMock struct {
-	pgx.Tx
+func (m *MockLoanService) StreamLoans(ctx context.Context, filter loan.LoanFilter, fn func(*loan.Loan) error) error {
+	args := m.Called(ctx, filter)
+	loans, _ := args.Get(0).([]*loan.Loan)
+	for _, l := range loans {
+		if err := fn(l); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
 }
 
-var tx pgx.Tx = &TxMock{}
+type MockLoanRepository struct {
+	mock.Mock
+}
 
 func (m *MockLoanRepository) CreateLoan(ctx context.Context, customerId int64, loanTest *loan.Loan, schedule []loan.ScheduleEntry) (*loan.Loan, error) {
 	args := m.Called(ctx, customerId, loanTest, schedule)
@@ -87,6 +261,21 @@ func (m *MockLoanRepository) GetLoanByID(ctx context.Context, loanID int64) (*lo
 	return args.Get(0).(*loan.Loan), args.Error(1)
 }
 
+func (m *MockLoanRepository) GetLoanWithSchedule(ctx context.Context, loanID int64) (*loan.Loan, error) {
+	args := m.Called(ctx, loanID)
+	return args.Get(0).(*loan.Loan), args.Error(1)
+}
+
+func (m *MockLoanRepository) GetLoanByExternalRef(ctx context.Context, externalRef string) (*loan.Loan, error) {
+	args := m.Called(ctx, externalRef)
+	return args.Get(0).(*loan.Loan), args.Error(1)
+}
+
+func (m *MockLoanRepository) GetLoanByPublicID(ctx context.Context, publicID uuid.UUID) (*loan.Loan, error) {
+	args := m.Called(ctx, publicID)
+	return args.Get(0).(*loan.Loan), args.Error(1)
+}
+
 func (m *MockLoanRepository) GetScheduleByLoanID(ctx context.Context, loanID int64) ([]loan.ScheduleEntry, error) {
 	args := m.Called(ctx, loanID)
 	return args.Get(0).([]loan.ScheduleEntry), args.Error(1)
@@ -102,64 +291,136 @@ func (m *MockLoanRepository) GetLastTwoDueUnpaidSchedules(ctx context.Context, l
 	return args.Get(0).([]loan.ScheduleEntry), args.Error(1)
 }
 
-func (m *MockLoanRepository) FindOldestUnpaidEntryForUpdate(ctx context.Context, tx pgx.Tx, loanID int64) (*loan.ScheduleEntry, error) {
-	args := m.Called(ctx, tx, loanID)
-	return args.Get(0).(*loan.ScheduleEntry), args.Error(1)
+func (m *MockLoanRepository) PlaceHold(ctx context.Context, hold *loan.Hold) error {
+	args := m.Called(ctx, hold)
+	return args.Error(0)
 }
 
-func (m *MockLoanRepository) UpdateScheduleEntryInTx(ctx context.Context, tx pgx.Tx, entry *loan.ScheduleEntry) error {
-	args := m.Called(ctx, tx, entry)
-	return args.Error(0)
+func (m *MockLoanRepository) ReleaseHold(ctx context.Context, loanID int64, releasedBy *string, releasedAt time.Time) (*loan.Hold, error) {
+	args := m.Called(ctx, loanID, releasedBy, releasedAt)
+	hold, _ := args.Get(0).(*loan.Hold)
+	return hold, args.Error(1)
 }
 
-func (m *MockLoanRepository) UpdateLoanStatusInTx(ctx context.Context, tx pgx.Tx, loanID int64, status loan.LoanStatus) error {
-	args := m.Called(ctx, tx, loanID, status)
-	return args.Error(0)
+func (m *MockLoanRepository) GetActiveHold(ctx context.Context, loanID int64) (*loan.Hold, error) {
+	args := m.Called(ctx, loanID)
+	hold, _ := args.Get(0).(*loan.Hold)
+	return hold, args.Error(1)
 }
 
-func (m *MockLoanRepository) CheckIfAllPaymentsMadeInTx(ctx context.Context, tx pgx.Tx, loanID int64) (bool, error) {
-	args := m.Called(ctx, tx, loanID)
-	return args.Bool(0), args.Error(1)
+func (m *MockLoanRepository) ListBalanceItems(ctx context.Context, loanID int64) ([]loan.BalanceItem, error) {
+	args := m.Called(ctx, loanID)
+	items, _ := args.Get(0).([]loan.BalanceItem)
+	return items, args.Error(1)
 }
 
-func (m *MockLoanRepository) GetTotalOutstandingAmount(ctx context.Context, loanID int64) (float64, error) {
+func (m *MockLoanRepository) GetLastModified(ctx context.Context, loanID int64) (time.Time, error) {
 	args := m.Called(ctx, loanID)
-	return args.Get(0).(float64), args.Error(1)
+	return args.Get(0).(time.Time), args.Error(1)
 }
 
-func (m *MockLoanRepository) BeginTx(ctx context.Context) (pgx.Tx, error) {
-	args := m.Called(ctx)
-	return args.Get(0).(pgx.Tx), args.Error(1)
+func (m *MockLoanRepository) SumPaymentsByChannel(ctx context.Context, from, to time.Time) ([]loan.ChannelCollections, error) {
+	args := m.Called(ctx, from, to)
+	totals, _ := args.Get(0).([]loan.ChannelCollections)
+	return totals, args.Error(1)
+}
+
+func (m *MockLoanRepository) ListPayments(ctx context.Context, loanID int64, limit int) ([]loan.Payment, error) {
+	args := m.Called(ctx, loanID, limit)
+	payments, _ := args.Get(0).([]loan.Payment)
+	return payments, args.Error(1)
+}
+
+func (m *MockLoanRepository) SearchLoans(ctx context.Context, q loan.LoanSearch) ([]loan.SearchMatch, error) {
+	args := m.Called(ctx, q)
+	matches, _ := args.Get(0).([]loan.SearchMatch)
+	return matches, args.Error(1)
+}
+
+func (m *MockLoanRepository) SumTaxLines(ctx context.Context, from, to time.Time) ([]loan.TaxTotals, error) {
+	args := m.Called(ctx, from, to)
+	totals, _ := args.Get(0).([]loan.TaxTotals)
+	return totals, args.Error(1)
+}
+
+func (m *MockLoanRepository) StreamLoans(ctx context.Context, filter loan.LoanFilter, fn func(*loan.Loan) error) error {
+	args := m.Called(ctx, filter)
+	loans, _ := args.Get(0).([]*loan.Loan)
+	for _, l := range loans {
+		if err := fn(l); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockLoanRepository) GetRateHistory(ctx context.Context, loanID int64) ([]loan.RateChange, error) {
+	args := m.Called(ctx, loanID)
+	history, _ := args.Get(0).([]loan.RateChange)
+	return history, args.Error(1)
+}
+
+func (m *MockLoanRepository) GetScheduleAdjustments(ctx context.Context, loanID int64) ([]loan.ScheduleAdjustment, error) {
+	args := m.Called(ctx, loanID)
+	adjustments, _ := args.Get(0).([]loan.ScheduleAdjustment)
+	return adjustments, args.Error(1)
 }
 
-func (m *MockLoanRepository) CommitTx(ctx context.Context, tx pgx.Tx) error {
-	args := m.Called(ctx, tx)
+func (m *MockLoanRepository) GetPrepayments(ctx context.Context, loanID int64) ([]loan.Prepayment, error) {
+	args := m.Called(ctx, loanID)
+	prepayments, _ := args.Get(0).([]loan.Prepayment)
+	return prepayments, args.Error(1)
+}
+
+func (m *MockLoanRepository) PostFee(ctx context.Context, fee *loan.Fee) error {
+	args := m.Called(ctx, fee)
 	return args.Error(0)
 }
 
-func (m *MockLoanRepository) RollbackTx(ctx context.Context, tx pgx.Tx) error {
-	args := m.Called(ctx, tx)
+func (m *MockLoanRepository) GetFees(ctx context.Context, loanID int64) ([]loan.Fee, error) {
+	args := m.Called(ctx, loanID)
+	fees, _ := args.Get(0).([]loan.Fee)
+	return fees, args.Error(1)
+}
+
+func (m *MockLoanRepository) RequestFeeWaiver(ctx context.Context, waiver *loan.FeeWaiver) error {
+	args := m.Called(ctx, waiver)
 	return args.Error(0)
 }
 
-func (m *MockLoanRepository) GetAllActiveLoanIDs(ctx context.Context) ([]int64, error) {
+func (m *MockLoanRepository) DecideFeeWaiver(ctx context.Context, waiver *loan.FeeWaiver) error {
+	args := m.Called(ctx, waiver)
+	return args.Error(0)
+}
+
+func (m *MockLoanRepository) WithinTransaction(ctx context.Context, fn func(tx loan.TxRepository) error) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func (m *MockLoanRepository) GetActiveLoanTenants(ctx context.Context) (map[int64]string, error) {
 	args := m.Called(ctx)
 	if args.Get(0) != nil {
-		return args.Get(0).([]int64), args.Error(1)
+		return args.Get(0).(map[int64]string), args.Error(1)
 	}
 	return nil, args.Error(1)
 }
 
+func (m *MockLoanRepository) UpdateDaysPastDue(ctx context.Context, loanID int64, daysPastDue int) error {
+	args := m.Called(ctx, loanID, daysPastDue)
+	return args.Error(0)
+}
+
 type MockCustomerService struct {
 	mock.Mock
 }
 
-func (_m *MockCustomerService) CreateNewCustomer(ctx context.Context, name string, address string) (*customer.Customer, error) {
-	ret := _m.Called(ctx, name, address)
+func (_m *MockCustomerService) CreateNewCustomer(ctx context.Context, name string, address string, externalRef string, publicID uuid.UUID, force bool) (*customer.Customer, error) {
+	ret := _m.Called(ctx, name, address, externalRef, publicID, force)
 
 	var r0 *customer.Customer
-	if rf, ok := ret.Get(0).(func(context.Context, string, string) *customer.Customer); ok {
-		r0 = rf(ctx, name, address)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, uuid.UUID, bool) *customer.Customer); ok {
+		r0 = rf(ctx, name, address, externalRef, publicID, force)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*customer.Customer)
@@ -167,8 +428,8 @@ func (_m *MockCustomerService) CreateNewCustomer(ctx context.Context, name strin
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
-		r1 = rf(ctx, name, address)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string, uuid.UUID, bool) error); ok {
+		r1 = rf(ctx, name, address, externalRef, publicID, force)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -176,6 +437,61 @@ func (_m *MockCustomerService) CreateNewCustomer(ctx context.Context, name strin
 	return r0, r1
 }
 
+func (_m *MockCustomerService) FindCustomerByExternalRef(ctx context.Context, externalRef string) (*customer.Customer, error) {
+	ret := _m.Called(ctx, externalRef)
+
+	var r0 *customer.Customer
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*customer.Customer)
+	}
+	return r0, ret.Error(1)
+}
+
+func (_m *MockCustomerService) ResolveCustomerID(ctx context.Context, publicID uuid.UUID) (int64, error) {
+	ret := _m.Called(ctx, publicID)
+	return ret.Get(0).(int64), ret.Error(1)
+}
+
+func (_m *MockCustomerService) GetPreferences(ctx context.Context, customerID int64) (*customer.Preferences, error) {
+	ret := _m.Called(ctx, customerID)
+	var r0 *customer.Preferences
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*customer.Preferences)
+	}
+	return r0, ret.Error(1)
+}
+
+func (_m *MockCustomerService) UpdatePreferences(ctx context.Context, p customer.Preferences) (*customer.Preferences, error) {
+	ret := _m.Called(ctx, p)
+	var r0 *customer.Preferences
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*customer.Preferences)
+	}
+	return r0, ret.Error(1)
+}
+
+func (_m *MockCustomerService) RequestRiskScore(ctx context.Context, cust *customer.Customer, reason customer.RiskScoreReason, principal float64) {
+	_m.Called(ctx, cust, reason, principal)
+}
+
+func (_m *MockCustomerService) UpdateRiskScore(ctx context.Context, a customer.RiskAssessment) (*customer.Customer, error) {
+	ret := _m.Called(ctx, a)
+	var r0 *customer.Customer
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*customer.Customer)
+	}
+	return r0, ret.Error(1)
+}
+
+func (_m *MockCustomerService) MergeCustomers(ctx context.Context, targetID, sourceID int64) (*customer.Merge, error) {
+	ret := _m.Called(ctx, targetID, sourceID)
+	var r0 *customer.Merge
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*customer.Merge)
+	}
+	return r0, ret.Error(1)
+}
+
 func (_m *MockCustomerService) GetCustomer(ctx context.Context, customerID int64) (*customer.Customer, error) {
 	ret := _m.Called(ctx, customerID)
 
@@ -220,6 +536,16 @@ func (_m *MockCustomerService) ListActiveCustomers(ctx context.Context) ([]*cust
 	return r0, r1
 }
 
+func (_m *MockCustomerService) ListActiveCustomersPage(ctx context.Context, afterID int64, limit int) ([]*customer.Customer, error) {
+	ret := _m.Called(ctx, afterID, limit)
+
+	var r0 []*customer.Customer
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*customer.Customer)
+	}
+	return r0, ret.Error(1)
+}
+
 func (_m *MockCustomerService) UpdateCustomerAddress(ctx context.Context, customerID int64, newAddress string) error {
 	ret := _m.Called(ctx, customerID, newAddress)
 
@@ -259,6 +585,33 @@ func (_m *MockCustomerService) UpdateDelinquency(ctx context.Context, customerID
 	return r0
 }
 
+func (_m *MockCustomerService) UpdateDelinquencyBulk(ctx context.Context, updates []customer.CustomerDelinquency) ([]*customer.Customer, error) {
+	ret := _m.Called(ctx, updates)
+
+	var r0 []*customer.Customer
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*customer.Customer)
+	}
+
+	return r0, ret.Error(1)
+}
+
+func (_m *MockCustomerService) NotifyDelinquency(ctx context.Context, notices []customer.DelinquencyNotice) ([]customer.DelinquencyNotice, error) {
+	ret := _m.Called(ctx, notices)
+
+	var r0 []customer.DelinquencyNotice
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]customer.DelinquencyNotice)
+	}
+
+	return r0, ret.Error(1)
+}
+
+func (_m *MockCustomerService) ClearDelinquencyNotices(ctx context.Context, customerIDs []int64) error {
+	ret := _m.Called(ctx, customerIDs)
+	return ret.Error(0)
+}
+
 func (_m *MockCustomerService) DeactivateCustomer(ctx context.Context, customerID int64) error {
 	ret := _m.Called(ctx, customerID)
 
@@ -307,23 +660,57 @@ func (_m *MockCustomerService) FindCustomerByLoan(ctx context.Context, loanID in
 	return r0, r1
 }
 
+// jobNow is the clock of the job under test: three installments are past
+// due.
+var jobNow = time.Date(2025, 2, 3, 10, 0, 0, 0, time.UTC)
+
+// pastDueSchedule returns the three installments due before jobNow; the
+// first paid of them were paid on their due date.
+func pastDueSchedule(paid int) []loan.ScheduleEntry {
+	schedule := make([]loan.ScheduleEntry, 3)
+	for i := range schedule {
+		due := time.Date(2025, 1, 13, 0, 0, 0, 0, time.UTC).AddDate(0, 0, 7*i)
+		schedule[i] = loan.ScheduleEntry{WeekNumber: i + 1, DueDate: due, DueAmount: 110, Status: loan.PaymentStatusPending}
+		if i < paid {
+			schedule[i].Status = loan.PaymentStatusPaid
+			schedule[i].PaidAmount = 110
+			schedule[i].PaymentDate = &due
+		}
+	}
+	return schedule
+}
+
 func TestUpdateDelinquencyJobRun(t *testing.T) {
 	ctx := context.Background()
 	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
 
 	t.Run("successfully processes loans", func(t *testing.T) {
-		activeLoanIDs := []int64{1, 2}
+		activeLoanIDs := defaultTenant(1, 2, 3)
 		mockLoanRepo, mockLoanService, mockCustomerService, job := newFunction(logger)
-		mockLoanRepo.On("GetAllActiveLoanIDs", ctx).Return(activeLoanIDs, nil)
-
-		mockLoanService.On("IsDelinquent", ctx, int64(1)).Return(true, nil)
-		mockLoanService.On("IsDelinquent", ctx, int64(2)).Return(false, nil)
+		mockLoanRepo.On("GetActiveLoanTenants", ctx).Return(activeLoanIDs, nil)
 
 		mockCustomerService.On("FindCustomerByLoan", ctx, int64(1)).Return(&customer.Customer{CustomerID: 101, IsDelinquent: false}, nil)
 		mockCustomerService.On("FindCustomerByLoan", ctx, int64(2)).Return(&customer.Customer{CustomerID: 102, IsDelinquent: true}, nil)
-
-		mockCustomerService.On("UpdateDelinquency", ctx, int64(101), true).Return(nil)
-		mockCustomerService.On("UpdateDelinquency", ctx, int64(102), false).Return(nil)
+		mockCustomerService.On("FindCustomerByLoan", ctx, int64(3)).Return(&customer.Customer{CustomerID: 103, IsDelinquent: true}, nil)
+
+		mockLoanService.On("GetLoanSchedule", ctx, int64(1)).Return(pastDueSchedule(0), nil)
+		mockLoanService.On("GetLoanSchedule", ctx, int64(2)).Return(pastDueSchedule(3), nil)
+		mockLoanService.On("GetLoanSchedule", ctx, int64(3)).Return(pastDueSchedule(2), nil)
+
+		mockLoanRepo.On("UpdateDaysPastDue", ctx, int64(1), 21).Return(nil)
+		mockLoanRepo.On("UpdateDaysPastDue", ctx, int64(2), 0).Return(nil)
+		mockLoanRepo.On("UpdateDaysPastDue", ctx, int64(3), 7).Return(nil)
+
+		mockCustomerService.On("UpdateDelinquencyBulk", ctx, []customer.CustomerDelinquency{
+			{CustomerID: 101, IsDelinquent: true},
+			{CustomerID: 102, IsDelinquent: false},
+		}).Return([]*customer.Customer{{CustomerID: 101, IsDelinquent: true}, {CustomerID: 102}}, nil).Once()
+		// Customer 103 was notified for the bucket on an earlier run.
+		mockCustomerService.On("NotifyDelinquency", ctx, []customer.DelinquencyNotice{
+			{CustomerID: 101, LoanID: 1, DaysPastDue: 21, Bucket: "1-30"},
+			{CustomerID: 103, LoanID: 3, DaysPastDue: 7, Bucket: "1-30"},
+		}).Return([]customer.DelinquencyNotice{{CustomerID: 101, LoanID: 1, DaysPastDue: 21, Bucket: "1-30"}}, nil).Once()
+		mockCustomerService.On("ClearDelinquencyNotices", ctx, []int64{102}).Return(nil).Once()
 
 		err := job.Run(ctx)
 		assert.NoError(t, err)
@@ -331,11 +718,12 @@ func TestUpdateDelinquencyJobRun(t *testing.T) {
 		mockLoanRepo.AssertExpectations(t)
 		mockLoanService.AssertExpectations(t)
 		mockCustomerService.AssertExpectations(t)
+		mockCustomerService.AssertNotCalled(t, "UpdateDelinquency", mock.Anything, mock.Anything, mock.Anything)
 	})
 
 	t.Run("handles repository error", func(t *testing.T) {
 		mockLoanRepo, _, _, job := newFunction(logger)
-		mockLoanRepo.On("GetAllActiveLoanIDs", ctx).Return(nil, fmt.Errorf("%w: failed to query active loans: %w", apperrors.ErrDatabase, nil))
+		mockLoanRepo.On("GetActiveLoanTenants", ctx).Return(nil, fmt.Errorf("%w: failed to query active loans: %w", apperrors.ErrDatabase, nil))
 
 		err := job.Run(ctx)
 		assert.Error(t, err)
@@ -345,38 +733,156 @@ func TestUpdateDelinquencyJobRun(t *testing.T) {
 	})
 
 	t.Run("handles loan service error", func(t *testing.T) {
-		activeLoanIDs := []int64{1}
-		mockLoanRepo, mockLoanService, _, job := newFunction(logger)
-		mockLoanRepo.On("GetAllActiveLoanIDs", ctx).Return(activeLoanIDs, nil)
+		activeLoanIDs := defaultTenant(1)
+		mockLoanRepo, mockLoanService, mockCustomerService, job := newFunction(logger)
+		mockLoanRepo.On("GetActiveLoanTenants", ctx).Return(activeLoanIDs, nil)
 
-		mockLoanService.On("IsDelinquent", ctx, int64(1)).Return(false, errors.New("loan service error"))
+		mockLoanService.On("GetLoanSchedule", ctx, int64(1)).Return(nil, errors.New("loan service error"))
 
 		err := job.Run(ctx)
 		assert.Error(t, err)
 
 		mockLoanRepo.AssertExpectations(t)
 		mockLoanService.AssertExpectations(t)
+		mockLoanRepo.AssertNotCalled(t, "UpdateDaysPastDue", mock.Anything, mock.Anything, mock.Anything)
+		mockCustomerService.AssertNotCalled(t, "FindCustomerByLoan", mock.Anything, mock.Anything)
+	})
+
+	t.Run("still updates the flag when days past due cannot be stored", func(t *testing.T) {
+		mockLoanRepo, mockLoanService, mockCustomerService, job := newFunction(logger)
+		mockLoanRepo.On("GetActiveLoanTenants", ctx).Return(defaultTenant(1), nil)
+		mockLoanService.On("GetLoanSchedule", ctx, int64(1)).Return(pastDueSchedule(0), nil)
+		mockLoanRepo.On("UpdateDaysPastDue", ctx, int64(1), 21).Return(apperrors.ErrDatabase)
+		mockCustomerService.On("FindCustomerByLoan", ctx, int64(1)).Return(&customer.Customer{CustomerID: 101}, nil)
+		mockCustomerService.On("UpdateDelinquencyBulk", ctx, []customer.CustomerDelinquency{{CustomerID: 101, IsDelinquent: true}}).
+			Return([]*customer.Customer{{CustomerID: 101, IsDelinquent: true}}, nil)
+		mockCustomerService.On("NotifyDelinquency", ctx, []customer.DelinquencyNotice{{CustomerID: 101, LoanID: 1, DaysPastDue: 21, Bucket: "1-30"}}).
+			Return([]customer.DelinquencyNotice{{CustomerID: 101, LoanID: 1, DaysPastDue: 21, Bucket: "1-30"}}, nil)
+
+		err := job.Run(ctx)
+		assert.Error(t, err)
+
+		mockLoanRepo.AssertExpectations(t)
+		mockCustomerService.AssertExpectations(t)
 	})
 
 	t.Run("handles customer service error", func(t *testing.T) {
-		activeLoanIDs := []int64{1}
+		activeLoanIDs := defaultTenant(1)
 		mockLoanRepo, mockLoanService, mockCustomerService, job := newFunction(logger)
-		mockLoanRepo.On("GetAllActiveLoanIDs", ctx).Return(activeLoanIDs, nil)
+		mockLoanRepo.On("GetActiveLoanTenants", ctx).Return(activeLoanIDs, nil)
 
-		mockLoanService.On("IsDelinquent", ctx, int64(1)).Return(true, nil)
+		mockLoanService.On("GetLoanSchedule", ctx, int64(1)).Return(pastDueSchedule(0), nil)
+		mockLoanRepo.On("UpdateDaysPastDue", ctx, int64(1), 21).Return(nil)
 		mockCustomerService.On("FindCustomerByLoan", ctx, int64(1)).Return(nil, errors.New("customer service error"))
 
 		err := job.Run(ctx)
 		assert.Error(t, err)
 
+		mockLoanRepo.AssertExpectations(t)
+		mockCustomerService.AssertExpectations(t)
+		mockCustomerService.AssertNotCalled(t, "UpdateDelinquencyBulk", mock.Anything, mock.Anything)
+	})
+
+	t.Run("reports a failed bulk update", func(t *testing.T) {
+		mockLoanRepo, mockLoanService, mockCustomerService, job := newFunction(logger)
+		mockLoanRepo.On("GetActiveLoanTenants", ctx).Return(defaultTenant(1), nil)
+		mockLoanService.On("GetLoanSchedule", ctx, int64(1)).Return(pastDueSchedule(0), nil)
+		mockLoanRepo.On("UpdateDaysPastDue", ctx, int64(1), 21).Return(nil)
+		mockCustomerService.On("FindCustomerByLoan", ctx, int64(1)).Return(&customer.Customer{CustomerID: 101}, nil)
+		mockCustomerService.On("UpdateDelinquencyBulk", ctx, []customer.CustomerDelinquency{{CustomerID: 101, IsDelinquent: true}}).
+			Return(nil, apperrors.ErrDatabase)
+
+		err := job.Run(ctx)
+		assert.Error(t, err)
+
+		mockCustomerService.AssertExpectations(t)
+		mockCustomerService.AssertNotCalled(t, "NotifyDelinquency", mock.Anything, mock.Anything)
+	})
+
+	t.Run("reports delinquency notices it could not send", func(t *testing.T) {
+		mockLoanRepo, mockLoanService, mockCustomerService, job := newFunction(logger)
+		mockLoanRepo.On("GetActiveLoanTenants", ctx).Return(defaultTenant(4), nil)
+		mockLoanService.On("GetLoanSchedule", ctx, int64(4)).Return(pastDueSchedule(0), nil)
+		mockLoanRepo.On("UpdateDaysPastDue", ctx, int64(4), 21).Return(nil)
+		mockCustomerService.On("FindCustomerByLoan", ctx, int64(4)).Return(&customer.Customer{CustomerID: 104, IsDelinquent: true}, nil)
+		mockCustomerService.On("NotifyDelinquency", ctx, []customer.DelinquencyNotice{{CustomerID: 104, LoanID: 4, DaysPastDue: 21, Bucket: "1-30"}}).
+			Return(nil, apperrors.ErrDatabase)
+
+		err := job.Run(ctx)
+		assert.Error(t, err)
+
+		mockCustomerService.AssertExpectations(t)
+		mockCustomerService.AssertNotCalled(t, "UpdateDelinquencyBulk", mock.Anything, mock.Anything)
+	})
+
+	t.Run("reports progress and the failed loans to its run", func(t *testing.T) {
+		mockLoanRepo, mockLoanService, mockCustomerService, job := newFunction(logger)
+		mockLoanRepo.On("GetActiveLoanTenants", mock.Anything).Return(defaultTenant(1, 2), nil)
+		mockLoanService.On("GetLoanSchedule", mock.Anything, int64(1)).Return(pastDueSchedule(3), nil)
+		mockLoanService.On("GetLoanSchedule", mock.Anything, int64(2)).Return(nil, errors.New("connection reset"))
+		mockLoanRepo.On("UpdateDaysPastDue", mock.Anything, int64(1), 0).Return(nil)
+		mockCustomerService.On("FindCustomerByLoan", mock.Anything, int64(1)).Return(&customer.Customer{CustomerID: 101}, nil)
+		mockCustomerService.On("ClearDelinquencyNotices", mock.Anything, []int64{101}).Return(nil)
+		recorder := jobs.NewRunRecorder(jobs.NewMemoryRunStore(), nil, logger)
+
+		err := recorder.Track(ctx, "DelinquencyUpdate", "job-1", job.Run)
+		assert.Error(t, err)
+
+		runs, err := recorder.List(ctx, "DelinquencyUpdate", 1)
+		require.NoError(t, err)
+		require.Len(t, runs, 1)
+		assert.Equal(t, 2, runs[0].Total)
+		assert.Equal(t, 2, runs[0].Processed)
+		assert.Equal(t, 1, runs[0].Failed)
+		assert.Equal(t, []string{"loan 2: connection reset"}, runs[0].ErrorSamples)
+	})
+
+	t.Run("stops when the policy cannot be read", func(t *testing.T) {
+		mockLoanRepo, mockLoanService, mockCustomerService := new(MockLoanRepository), new(MockLoanService), new(MockCustomerService)
+		policy := failingPolicy{err: fmt.Errorf("%w: failed to get tenant settings", apperrors.ErrDatabase)}
+		job := batch.NewUpdateDelinquencyJob(mockLoanRepo, mockLoanService, mockCustomerService, policy, clock.NewFake(jobNow), logger)
+		mockLoanRepo.On("GetActiveLoanTenants", ctx).Return(defaultTenant(1), nil)
+
+		err := job.Run(ctx)
+		assert.ErrorIs(t, err, apperrors.ErrDatabase)
+		mockLoanService.AssertNotCalled(t, "GetLoanSchedule", mock.Anything, mock.Anything)
+		mockCustomerService.AssertNotCalled(t, "UpdateDelinquencyBulk", mock.Anything, mock.Anything)
+	})
+
+	t.Run("judges each loan by its tenant's policy and charges its late fee", func(t *testing.T) {
+		mockLoanRepo, mockLoanService, mockCustomerService := new(MockLoanRepository), new(MockLoanService), new(MockCustomerService)
+		// acme flags after three missed installments and charges a late fee
+		// a week after the due date.
+		acme := loan.DelinquencyPolicy{MissedPayments: 3, CurePayments: 2, GraceDays: 7, LateFee: 15}
+		policies := tenantPolicies{scope.DefaultTenant: loan.DefaultDelinquencyPolicy(), "acme": acme}
+		job := batch.NewUpdateDelinquencyJob(mockLoanRepo, mockLoanService, mockCustomerService, policies, clock.NewFake(jobNow), logger)
+		mockLoanRepo.On("GetActiveLoanTenants", ctx).Return(map[int64]string{1: scope.DefaultTenant, 2: "acme", 3: "acme"}, nil)
+		for id := int64(1); id <= 3; id++ {
+			mockLoanService.On("GetLoanSchedule", ctx, id).Return(pastDueSchedule(0), nil)
+			mockLoanRepo.On("UpdateDaysPastDue", ctx, id, 21).Return(nil)
+			mockCustomerService.On("FindCustomerByLoan", ctx, id).Return(&customer.Customer{CustomerID: 100 + id}, nil)
+		}
+		mockLoanService.On("ChargeLateFees", ctx, int64(2), acme).Return([]loan.Fee{{ID: 8, LoanID: 2, Type: loan.FeeLate}, {ID: 9, LoanID: 2, Type: loan.FeeLate}}, nil).Once()
+		mockLoanService.On("ChargeLateFees", ctx, int64(3), acme).Return(nil, fmt.Errorf("%w: loan 3 is on hold: fraud review", apperrors.ErrLoanOnHold)).Once()
+		// Three installments are past due, but the grace period of acme
+		// leaves two of them missed.
+		mockCustomerService.On("UpdateDelinquencyBulk", ctx, []customer.CustomerDelinquency{{CustomerID: 101, IsDelinquent: true}}).
+			Return([]*customer.Customer{{CustomerID: 101, IsDelinquent: true}}, nil).Once()
+		mockCustomerService.On("NotifyDelinquency", ctx, mock.Anything).Return([]customer.DelinquencyNotice{}, nil).Once()
+		mockCustomerService.On("ClearDelinquencyNotices", ctx, []int64{102, 103}).Return(nil).Once()
+
+		err := job.Run(ctx)
+
+		require.NoError(t, err, "a loan on hold is skipped, not failed")
 		mockLoanRepo.AssertExpectations(t)
 		mockLoanService.AssertExpectations(t)
+		mockLoanService.AssertNotCalled(t, "ChargeLateFees", mock.Anything, int64(1), mock.Anything)
 		mockCustomerService.AssertExpectations(t)
 	})
 
 	t.Run("handles no active loans", func(t *testing.T) {
 		mockLoanRepo, _, _, job := newFunction(logger)
-		mockLoanRepo.On("GetAllActiveLoanIDs", ctx).Return([]int64{}, nil)
+		mockLoanRepo.On("GetActiveLoanTenants", ctx).Return(defaultTenant(), nil)
 
 		err := job.Run(ctx)
 		assert.NoError(t, err)
@@ -385,11 +891,35 @@ func TestUpdateDelinquencyJobRun(t *testing.T) {
 	})
 }
 
+// defaultTenant puts the loans in the default tenant, as
+// GetActiveLoanTenants returns them.
+func defaultTenant(loanIDs ...int64) map[int64]string {
+	tenants := make(map[int64]string, len(loanIDs))
+	for _, id := range loanIDs {
+		tenants[id] = scope.DefaultTenant
+	}
+	return tenants
+}
+
+// tenantPolicies is a delinquency policy source with a policy per tenant.
+type tenantPolicies map[string]loan.DelinquencyPolicy
+
+func (p tenantPolicies) Current(ctx context.Context) (loan.DelinquencyPolicy, error) {
+	return p[scope.TenantOrDefault(ctx)], nil
+}
+
+// failingPolicy is a delinquency policy source that cannot be read.
+type failingPolicy struct{ err error }
+
+func (p failingPolicy) Current(context.Context) (loan.DelinquencyPolicy, error) {
+	return loan.DelinquencyPolicy{}, p.err
+}
+
 func newFunction(logger *slog.Logger) (*MockLoanRepository, *MockLoanService, *MockCustomerService, *batch.UpdateDelinquencyJob) {
 	mockLoanRepo := new(MockLoanRepository)
 	mockLoanService := new(MockLoanService)
 	mockCustomerService := new(MockCustomerService)
 
-	job := batch.NewUpdateDelinquencyJob(mockLoanRepo, mockLoanService, mockCustomerService, logger)
+	job := batch.NewUpdateDelinquencyJob(mockLoanRepo, mockLoanService, mockCustomerService, loan.DefaultDelinquencyPolicy(), clock.NewFake(jobNow), logger)
 	return mockLoanRepo, mockLoanService, mockCustomerService, job
 }
@@ -0,0 +1,107 @@
+package batch
+
+import (
+	"billing-engine/internal/domain/directdebit"
+	"billing-engine/internal/jobs"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DirectDebitJob turns the installments due in the coming week into
+// direct-debit instructions and writes them to a bank file in exportDir,
+// where the transfer to the bank picks it up.
+type DirectDebitJob struct {
+	directDebitService directdebit.Service
+	exportDir          string
+	clock              clock.Clock
+	logger             *slog.Logger
+}
+
+// NewDirectDebitJob builds the job. Files are named after the batch
+// reference. The clock decides which installments are due.
+func NewDirectDebitJob(directDebitSvc directdebit.Service, exportDir string, clk clock.Clock, logger *slog.Logger) *DirectDebitJob {
+	if directDebitSvc == nil || clk == nil || logger == nil {
+		panic("DirectDebitJob dependencies cannot be nil")
+	}
+	return &DirectDebitJob{
+		directDebitService: directDebitSvc,
+		exportDir:          exportDir,
+		clock:              clk,
+		logger:             logger.With("job", "DirectDebit"),
+	}
+}
+
+func (j *DirectDebitJob) Run(ctx context.Context) error {
+	startTime := time.Now()
+	j.logger.InfoContext(ctx, "Starting weekly direct-debit job.")
+
+	created, err := j.directDebitService.GenerateInstructions(ctx, j.clock.Now().UTC())
+	if err != nil {
+		j.logger.ErrorContext(ctx, "Direct-debit instruction generation failed.", slog.Any("error", err))
+		return fmt.Errorf("direct-debit job failed: %w", err)
+	}
+
+	batch, path, err := j.export(ctx)
+	if err != nil {
+		j.logger.ErrorContext(ctx, "Direct-debit export failed.", slog.Any("error", err))
+		return fmt.Errorf("direct-debit job failed: %w", err)
+	}
+	if batch.Instructions == 0 {
+		j.logger.InfoContext(ctx, "Direct-debit job finished, nothing to collect.",
+			slog.Duration("duration", time.Since(startTime)))
+		return nil
+	}
+
+	jobs.ProgressFrom(ctx).Done(batch.Instructions)
+	j.logger.InfoContext(ctx, "Direct-debit job finished.",
+		slog.Int("generated", created),
+		slog.String("batch", batch.Ref),
+		slog.Int("instructions", batch.Instructions),
+		slog.Float64("total", batch.Total),
+		slog.String("file", path),
+		slog.Duration("duration", time.Since(startTime)))
+	return nil
+}
+
+// export writes the batch to a temporary file and renames it into place
+// once the instructions are marked exported, so the transfer never sees a
+// partial file. From then on the file is the only record of what was sent,
+// so it is kept even when the rename fails.
+func (j *DirectDebitJob) export(ctx context.Context) (*directdebit.Batch, string, error) {
+	if err := os.MkdirAll(j.exportDir, 0o750); err != nil {
+		return nil, "", fmt.Errorf("failed to create export directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(j.exportDir, ".direct-debit-*.tmp")
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create bank file: %w", err)
+	}
+	keep := false
+	defer func() {
+		if !keep {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	batch, err := j.directDebitService.ExportBatch(ctx, tmp)
+	if err != nil {
+		return nil, "", err
+	}
+	if batch.Instructions == 0 {
+		return batch, "", nil
+	}
+	keep = true
+	if err := tmp.Close(); err != nil {
+		return nil, "", fmt.Errorf("failed to write bank file %s for batch %s: %w", tmp.Name(), batch.Ref, err)
+	}
+	path := filepath.Join(j.exportDir, batch.Ref+directdebit.Extension(batch.Format))
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return nil, "", fmt.Errorf("failed to move bank file %s of batch %s into place: %w", tmp.Name(), batch.Ref, err)
+	}
+	return batch, path, nil
+}
@@ -0,0 +1,118 @@
+package batch_test
+
+import (
+	"billing-engine/internal/batch"
+	"billing-engine/internal/domain/directdebit"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockDirectDebitService struct {
+	mock.Mock
+}
+
+func (m *MockDirectDebitService) CreateMandate(ctx context.Context, mandate *directdebit.Mandate) (*directdebit.Mandate, error) {
+	args := m.Called(ctx, mandate)
+	created, _ := args.Get(0).(*directdebit.Mandate)
+	return created, args.Error(1)
+}
+
+func (m *MockDirectDebitService) ListMandates(ctx context.Context, customerID int64) ([]directdebit.Mandate, error) {
+	args := m.Called(ctx, customerID)
+	return args.Get(0).([]directdebit.Mandate), args.Error(1)
+}
+
+func (m *MockDirectDebitService) CancelMandate(ctx context.Context, customerID, mandateID int64) error {
+	return m.Called(ctx, customerID, mandateID).Error(0)
+}
+
+func (m *MockDirectDebitService) GenerateInstructions(ctx context.Context, asOf time.Time) (int, error) {
+	args := m.Called(ctx, asOf)
+	return args.Int(0), args.Error(1)
+}
+
+// ExportBatch writes the "contents" argument before returning the batch.
+func (m *MockDirectDebitService) ExportBatch(ctx context.Context, w io.Writer) (*directdebit.Batch, error) {
+	args := m.Called(ctx, w)
+	if contents, ok := args.Get(2).(string); ok {
+		_, _ = io.WriteString(w, contents)
+	}
+	batch, _ := args.Get(0).(*directdebit.Batch)
+	return batch, args.Error(1)
+}
+
+func (m *MockDirectDebitService) ProcessResults(ctx context.Context, rows []directdebit.ResultRow, progress func(int)) *directdebit.ResultReport {
+	return m.Called(ctx, rows).Get(0).(*directdebit.ResultReport)
+}
+
+func TestDirectDebitJobRun(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	now := time.Date(2025, 1, 6, 6, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(now)
+
+	t.Run("writes the batch under its reference", func(t *testing.T) {
+		dir := t.TempDir()
+		service := new(MockDirectDebitService)
+		service.On("GenerateInstructions", ctx, now).Return(2, nil)
+		service.On("ExportBatch", ctx, mock.Anything).
+			Return(&directdebit.Batch{Ref: "DD-20250106-AB12CD34", Format: directdebit.FormatPain008, Instructions: 2, Total: 220}, nil, "<Document/>")
+
+		err := batch.NewDirectDebitJob(service, dir, clk, logger).Run(ctx)
+
+		require.NoError(t, err)
+		contents, err := os.ReadFile(filepath.Join(dir, "DD-20250106-AB12CD34.xml"))
+		require.NoError(t, err)
+		assert.Equal(t, "<Document/>", string(contents))
+		entries, _ := os.ReadDir(dir)
+		assert.Len(t, entries, 1, "the temporary file is renamed")
+		service.AssertExpectations(t)
+	})
+
+	t.Run("leaves no file when nothing is due", func(t *testing.T) {
+		dir := t.TempDir()
+		service := new(MockDirectDebitService)
+		service.On("GenerateInstructions", ctx, now).Return(0, nil)
+		service.On("ExportBatch", ctx, mock.Anything).
+			Return(&directdebit.Batch{Ref: "DD-20250106-AB12CD34", Format: directdebit.FormatCSV}, nil, nil)
+
+		require.NoError(t, batch.NewDirectDebitJob(service, dir, clk, logger).Run(ctx))
+
+		entries, _ := os.ReadDir(dir)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("discards a partly written file", func(t *testing.T) {
+		dir := t.TempDir()
+		service := new(MockDirectDebitService)
+		service.On("GenerateInstructions", ctx, now).Return(1, nil)
+		service.On("ExportBatch", ctx, mock.Anything).Return(nil, errors.New("database error"), "end_to_end_id,")
+
+		err := batch.NewDirectDebitJob(service, dir, clk, logger).Run(ctx)
+
+		assert.ErrorContains(t, err, "database error")
+		entries, _ := os.ReadDir(dir)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("does not export when generation fails", func(t *testing.T) {
+		service := new(MockDirectDebitService)
+		service.On("GenerateInstructions", ctx, now).Return(0, errors.New("database error"))
+
+		err := batch.NewDirectDebitJob(service, t.TempDir(), clk, logger).Run(ctx)
+
+		assert.ErrorContains(t, err, "database error")
+		service.AssertNotCalled(t, "ExportBatch", mock.Anything, mock.Anything)
+	})
+}
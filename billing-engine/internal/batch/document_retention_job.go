@@ -0,0 +1,47 @@
+package batch
+
+import (
+	"billing-engine/internal/domain/document"
+	"billing-engine/internal/jobs"
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// DocumentRetentionJob deletes the documents past their retention period
+// and the uploads that were never confirmed. A document it fails to delete
+// is kept and tried again by the next run.
+type DocumentRetentionJob struct {
+	documentService document.Service
+	logger          *slog.Logger
+}
+
+func NewDocumentRetentionJob(documentSvc document.Service, logger *slog.Logger) *DocumentRetentionJob {
+	if documentSvc == nil || logger == nil {
+		panic("DocumentRetentionJob dependencies cannot be nil")
+	}
+	return &DocumentRetentionJob{
+		documentService: documentSvc,
+		logger:          logger.With("job", "DocumentRetention"),
+	}
+}
+
+func (j *DocumentRetentionJob) Run(ctx context.Context) error {
+	startTime := time.Now()
+	j.logger.InfoContext(ctx, "Starting document retention job.")
+
+	report, err := j.documentService.ApplyRetention(ctx)
+	if err != nil {
+		j.logger.ErrorContext(ctx, "Document retention job failed.", slog.Any("error", err))
+		return fmt.Errorf("document retention job failed: %w", err)
+	}
+
+	jobs.ProgressFrom(ctx).Done(report.Expired + report.Abandoned + report.Failed)
+	j.logger.InfoContext(ctx, "Document retention job finished.",
+		slog.Int("expired", report.Expired),
+		slog.Int("abandoned", report.Abandoned),
+		slog.Int("failed", report.Failed),
+		slog.Duration("duration", time.Since(startTime)))
+	return nil
+}
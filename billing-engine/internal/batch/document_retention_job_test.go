@@ -0,0 +1,37 @@
+package batch_test
+
+import (
+	"billing-engine/internal/batch"
+	"billing-engine/internal/domain/document"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDocumentRetentionJobRun(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	t.Run("succeeds when some documents fail", func(t *testing.T) {
+		documents := new(MockDocumentService)
+		documents.On("ApplyRetention", ctx).Return(&document.RetentionReport{Expired: 3, Abandoned: 1, Failed: 1}, nil)
+
+		err := batch.NewDocumentRetentionJob(documents, logger).Run(ctx)
+
+		assert.NoError(t, err, "failed documents are tried again by the next run")
+		documents.AssertExpectations(t)
+	})
+
+	t.Run("fails when the documents cannot be listed", func(t *testing.T) {
+		documents := new(MockDocumentService)
+		documents.On("ApplyRetention", ctx).Return(nil, apperrors.ErrDatabase)
+
+		err := batch.NewDocumentRetentionJob(documents, logger).Run(ctx)
+
+		assert.ErrorIs(t, err, apperrors.ErrDatabase)
+	})
+}
@@ -0,0 +1,43 @@
+package batch
+
+import (
+	"billing-engine/internal/domain/integrity"
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// IntegrityCheckJob runs the data integrity checks. The violations end up in
+// the integrity_findings table and the violation gauges; the job itself only
+// fails when a check could not run.
+type IntegrityCheckJob struct {
+	integrityService integrity.Service
+	logger           *slog.Logger
+}
+
+func NewIntegrityCheckJob(integritySvc integrity.Service, logger *slog.Logger) *IntegrityCheckJob {
+	if integritySvc == nil || logger == nil {
+		panic("IntegrityCheckJob dependencies cannot be nil")
+	}
+	return &IntegrityCheckJob{
+		integrityService: integritySvc,
+		logger:           logger.With("job", "IntegrityCheck"),
+	}
+}
+
+func (j *IntegrityCheckJob) Run(ctx context.Context) error {
+	startTime := time.Now()
+	j.logger.InfoContext(ctx, "Starting data integrity check job.")
+
+	report, err := j.integrityService.Run(ctx)
+	if err != nil {
+		j.logger.ErrorContext(ctx, "Data integrity check job failed.", slog.Any("error", err))
+		return fmt.Errorf("data integrity check job failed: %w", err)
+	}
+
+	j.logger.InfoContext(ctx, "Data integrity check job finished.",
+		slog.Int("violations", report.Total()),
+		slog.Duration("duration", time.Since(startTime)))
+	return nil
+}
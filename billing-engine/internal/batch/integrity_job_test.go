@@ -0,0 +1,54 @@
+package batch_test
+
+import (
+	"billing-engine/internal/batch"
+	"billing-engine/internal/domain/integrity"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockIntegrityService struct {
+	mock.Mock
+}
+
+func (m *MockIntegrityService) Run(ctx context.Context) (*integrity.Report, error) {
+	args := m.Called(ctx)
+	r, _ := args.Get(0).(*integrity.Report)
+	return r, args.Error(1)
+}
+
+func (m *MockIntegrityService) ListFindings(ctx context.Context, check string) ([]integrity.Finding, error) {
+	args := m.Called(ctx, check)
+	f, _ := args.Get(0).([]integrity.Finding)
+	return f, args.Error(1)
+}
+
+func TestIntegrityCheckJobRun(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	t.Run("does not fail on violations", func(t *testing.T) {
+		service := new(MockIntegrityService)
+		service.On("Run", ctx).Return(&integrity.Report{Violations: map[integrity.Check]int{integrity.CheckLedgerMismatch: 2}}, nil)
+
+		err := batch.NewIntegrityCheckJob(service, logger).Run(ctx)
+
+		assert.NoError(t, err)
+		service.AssertExpectations(t)
+	})
+
+	t.Run("returns service error", func(t *testing.T) {
+		service := new(MockIntegrityService)
+		service.On("Run", ctx).Return(nil, errors.New("database error"))
+
+		err := batch.NewIntegrityCheckJob(service, logger).Run(ctx)
+
+		assert.ErrorContains(t, err, "database error")
+	})
+}
@@ -0,0 +1,66 @@
+package batch
+
+import (
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/domain/summary"
+	"billing-engine/internal/jobs"
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// LoanArchiveJob moves paid-off loans past the retention period into the
+// archive tables and refreshes the summaries of the customers they belonged
+// to. A summary that fails to refresh is logged and left to the nightly
+// rebuild.
+type LoanArchiveJob struct {
+	archiveService loan.ArchiveService
+	summaryService summary.Service
+	logger         *slog.Logger
+}
+
+func NewLoanArchiveJob(archiveSvc loan.ArchiveService, summarySvc summary.Service, logger *slog.Logger) *LoanArchiveJob {
+	if archiveSvc == nil || summarySvc == nil || logger == nil {
+		panic("LoanArchiveJob dependencies cannot be nil")
+	}
+	return &LoanArchiveJob{
+		archiveService: archiveSvc,
+		summaryService: summarySvc,
+		logger:         logger.With("job", "LoanArchive"),
+	}
+}
+
+func (j *LoanArchiveJob) Run(ctx context.Context) error {
+	startTime := time.Now()
+	j.logger.InfoContext(ctx, "Starting loan archive job.")
+
+	report, err := j.archiveService.ArchiveCompleted(ctx)
+	if report != nil {
+		j.refreshSummaries(ctx, report.Archived)
+	}
+	if err != nil {
+		j.logger.ErrorContext(ctx, "Loan archive job failed.", slog.Any("error", err))
+		return fmt.Errorf("loan archive job failed: %w", err)
+	}
+
+	jobs.ProgressFrom(ctx).Done(len(report.Archived) + report.Skipped)
+	j.logger.InfoContext(ctx, "Loan archive job finished.",
+		slog.Int("archived", len(report.Archived)),
+		slog.Int("skipped", report.Skipped),
+		slog.Time("cutoff", report.Cutoff),
+		slog.Duration("duration", time.Since(startTime)))
+	return nil
+}
+
+func (j *LoanArchiveJob) refreshSummaries(ctx context.Context, archived []loan.ArchivedLoan) {
+	for _, a := range archived {
+		if a.CustomerID == nil {
+			continue
+		}
+		if err := j.summaryService.Refresh(ctx, *a.CustomerID); err != nil {
+			j.logger.WarnContext(ctx, "Failed to refresh summary after archiving loan",
+				slog.Int64("loan_id", a.LoanID), slog.Int64("customer_id", *a.CustomerID), slog.Any("error", err))
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package batch_test
+
+import (
+	"billing-engine/internal/batch"
+	"billing-engine/internal/domain/loan"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockArchiveService struct {
+	mock.Mock
+}
+
+func (m *MockArchiveService) ArchiveCompleted(ctx context.Context) (*loan.ArchiveReport, error) {
+	args := m.Called(ctx)
+	r, _ := args.Get(0).(*loan.ArchiveReport)
+	return r, args.Error(1)
+}
+
+func (m *MockArchiveService) Restore(ctx context.Context, loanID int64) (*loan.ArchivedLoan, bool, error) {
+	args := m.Called(ctx, loanID)
+	a, _ := args.Get(0).(*loan.ArchivedLoan)
+	return a, args.Bool(1), args.Error(2)
+}
+
+func (m *MockArchiveService) ListArchived(ctx context.Context, limit int) ([]loan.ArchivedLoan, error) {
+	args := m.Called(ctx, limit)
+	a, _ := args.Get(0).([]loan.ArchivedLoan)
+	return a, args.Error(1)
+}
+
+func TestLoanArchiveJobRun(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	customerID := int64(4)
+
+	t.Run("refreshes the summaries of archived loans", func(t *testing.T) {
+		archives := new(MockArchiveService)
+		summaries := new(MockSummaryService)
+		archives.On("ArchiveCompleted", ctx).Return(&loan.ArchiveReport{Archived: []loan.ArchivedLoan{
+			{LoanID: 1, CustomerID: &customerID},
+			{LoanID: 2},
+		}}, nil)
+		summaries.On("Refresh", ctx, customerID).Return(errors.New("database error"))
+
+		err := batch.NewLoanArchiveJob(archives, summaries, logger).Run(ctx)
+
+		assert.NoError(t, err, "a failed refresh is left to the nightly rebuild")
+		archives.AssertExpectations(t)
+		summaries.AssertExpectations(t)
+	})
+
+	t.Run("refreshes what was archived before an error", func(t *testing.T) {
+		archives := new(MockArchiveService)
+		summaries := new(MockSummaryService)
+		archives.On("ArchiveCompleted", ctx).Return(&loan.ArchiveReport{Archived: []loan.ArchivedLoan{
+			{LoanID: 1, CustomerID: &customerID},
+		}}, errors.New("connection reset"))
+		summaries.On("Refresh", ctx, customerID).Return(nil)
+
+		err := batch.NewLoanArchiveJob(archives, summaries, logger).Run(ctx)
+
+		assert.ErrorContains(t, err, "connection reset")
+		summaries.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,53 @@
+package batch
+
+import (
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/jobs"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// PartitionMaintenanceJob creates the partitions of loan_schedule and
+// payments before rows arrive for them, so that they do not pile up in the
+// default partitions. Running it daily is cheap: partitions that exist are
+// left alone.
+type PartitionMaintenanceJob struct {
+	partitions  loan.PartitionRepository
+	monthsAhead int
+	clock       clock.Clock
+	logger      *slog.Logger
+}
+
+// NewPartitionMaintenanceJob builds the job. The clock is the one payments
+// are stamped with, so the months it prepares are those paid_at will fall in.
+func NewPartitionMaintenanceJob(partitions loan.PartitionRepository, monthsAhead int, clk clock.Clock, logger *slog.Logger) *PartitionMaintenanceJob {
+	if partitions == nil || clk == nil || logger == nil {
+		panic("PartitionMaintenanceJob dependencies cannot be nil")
+	}
+	return &PartitionMaintenanceJob{
+		partitions:  partitions,
+		monthsAhead: max(monthsAhead, 1),
+		clock:       clk,
+		logger:      logger.With("job", "PartitionMaintenance"),
+	}
+}
+
+func (j *PartitionMaintenanceJob) Run(ctx context.Context) error {
+	startTime := time.Now()
+	j.logger.InfoContext(ctx, "Starting partition maintenance job.")
+
+	created, err := j.partitions.EnsurePartitions(ctx, j.clock.Now(), j.monthsAhead)
+	if err != nil {
+		j.logger.ErrorContext(ctx, "Partition maintenance job failed.", slog.Any("created", created), slog.Any("error", err))
+		return fmt.Errorf("partition maintenance job failed: %w", err)
+	}
+
+	jobs.ProgressFrom(ctx).Done(len(created))
+	j.logger.InfoContext(ctx, "Partition maintenance job finished.",
+		slog.Any("created", created),
+		slog.Duration("duration", time.Since(startTime)))
+	return nil
+}
@@ -0,0 +1,58 @@
+package batch_test
+
+import (
+	"billing-engine/internal/batch"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockPartitionRepository struct {
+	mock.Mock
+}
+
+func (m *MockPartitionRepository) EnsurePartitions(ctx context.Context, now time.Time, monthsAhead int) ([]string, error) {
+	args := m.Called(ctx, now, monthsAhead)
+	created, _ := args.Get(0).([]string)
+	return created, args.Error(1)
+}
+
+func TestPartitionMaintenanceJobRun(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewFake(time.Date(2025, 3, 10, 1, 15, 0, 0, time.UTC))
+
+	t.Run("prepares the coming months", func(t *testing.T) {
+		repo := new(MockPartitionRepository)
+		repo.On("EnsurePartitions", ctx, clk.Now(), 3).Return([]string{"payments_y2025m06"}, nil)
+
+		err := batch.NewPartitionMaintenanceJob(repo, 3, clk, logger).Run(ctx)
+
+		assert.NoError(t, err)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("looks at least a month ahead", func(t *testing.T) {
+		repo := new(MockPartitionRepository)
+		repo.On("EnsurePartitions", ctx, clk.Now(), 1).Return(nil, nil)
+
+		assert.NoError(t, batch.NewPartitionMaintenanceJob(repo, 0, clk, logger).Run(ctx))
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("returns repository error", func(t *testing.T) {
+		repo := new(MockPartitionRepository)
+		repo.On("EnsurePartitions", ctx, clk.Now(), 3).Return(nil, errors.New("conflict: the default partition of payments holds rows"))
+
+		err := batch.NewPartitionMaintenanceJob(repo, 3, clk, logger).Run(ctx)
+
+		assert.ErrorContains(t, err, "default partition")
+	})
+}
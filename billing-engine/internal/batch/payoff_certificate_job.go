@@ -0,0 +1,46 @@
+package batch
+
+import (
+	"billing-engine/internal/domain/document"
+	"billing-engine/internal/jobs"
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// PayoffCertificateJob issues the certificates of recently paid-off loans.
+// A loan it fails for is logged by the service and picked up by the next
+// run, so only a run that cannot list the loans fails.
+type PayoffCertificateJob struct {
+	documentService document.Service
+	logger          *slog.Logger
+}
+
+func NewPayoffCertificateJob(documentSvc document.Service, logger *slog.Logger) *PayoffCertificateJob {
+	if documentSvc == nil || logger == nil {
+		panic("PayoffCertificateJob dependencies cannot be nil")
+	}
+	return &PayoffCertificateJob{
+		documentService: documentSvc,
+		logger:          logger.With("job", "PayoffCertificates"),
+	}
+}
+
+func (j *PayoffCertificateJob) Run(ctx context.Context) error {
+	startTime := time.Now()
+	j.logger.InfoContext(ctx, "Starting payoff certificate job.")
+
+	report, err := j.documentService.IssuePayoffCertificates(ctx)
+	if err != nil {
+		j.logger.ErrorContext(ctx, "Payoff certificate job failed.", slog.Any("error", err))
+		return fmt.Errorf("payoff certificate job failed: %w", err)
+	}
+
+	jobs.ProgressFrom(ctx).Done(len(report.Issued) + report.Failed)
+	j.logger.InfoContext(ctx, "Payoff certificate job finished.",
+		slog.Int("issued", len(report.Issued)),
+		slog.Int("failed", report.Failed),
+		slog.Duration("duration", time.Since(startTime)))
+	return nil
+}
@@ -0,0 +1,90 @@
+package batch_test
+
+import (
+	"billing-engine/internal/batch"
+	"billing-engine/internal/domain/document"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockDocumentService struct {
+	mock.Mock
+}
+
+func (m *MockDocumentService) IssuePayoffCertificates(ctx context.Context) (*document.IssueReport, error) {
+	args := m.Called(ctx)
+	r, _ := args.Get(0).(*document.IssueReport)
+	return r, args.Error(1)
+}
+
+func (m *MockDocumentService) IssueLoanAgreement(ctx context.Context, agreement document.LoanAgreement) (*document.Document, []byte, error) {
+	args := m.Called(ctx, agreement)
+	d, _ := args.Get(0).(*document.Document)
+	content, _ := args.Get(1).([]byte)
+	return d, content, args.Error(2)
+}
+
+func (m *MockDocumentService) RequestUpload(ctx context.Context, subject document.Subject, upload document.Upload) (*document.PendingUpload, error) {
+	args := m.Called(ctx, subject, upload)
+	p, _ := args.Get(0).(*document.PendingUpload)
+	return p, args.Error(1)
+}
+
+func (m *MockDocumentService) ConfirmUpload(ctx context.Context, subject document.Subject, documentID int64) (*document.Document, error) {
+	args := m.Called(ctx, subject, documentID)
+	d, _ := args.Get(0).(*document.Document)
+	return d, args.Error(1)
+}
+
+func (m *MockDocumentService) List(ctx context.Context, subject document.Subject) ([]document.Document, error) {
+	args := m.Called(ctx, subject)
+	d, _ := args.Get(0).([]document.Document)
+	return d, args.Error(1)
+}
+
+func (m *MockDocumentService) Open(ctx context.Context, subject document.Subject, documentID int64) (*document.Document, io.ReadCloser, error) {
+	args := m.Called(ctx, subject, documentID)
+	d, _ := args.Get(0).(*document.Document)
+	body, _ := args.Get(1).(io.ReadCloser)
+	return d, body, args.Error(2)
+}
+
+func (m *MockDocumentService) Delete(ctx context.Context, subject document.Subject, documentID int64) error {
+	return m.Called(ctx, subject, documentID).Error(0)
+}
+
+func (m *MockDocumentService) ApplyRetention(ctx context.Context) (*document.RetentionReport, error) {
+	args := m.Called(ctx)
+	r, _ := args.Get(0).(*document.RetentionReport)
+	return r, args.Error(1)
+}
+
+func TestPayoffCertificateJobRun(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	t.Run("succeeds when some loans fail", func(t *testing.T) {
+		documents := new(MockDocumentService)
+		documents.On("IssuePayoffCertificates", ctx).Return(&document.IssueReport{Issued: []document.Document{{ID: 1}}, Failed: 1}, nil)
+
+		err := batch.NewPayoffCertificateJob(documents, logger).Run(ctx)
+
+		assert.NoError(t, err, "failed loans are tried again by the next run")
+		documents.AssertExpectations(t)
+	})
+
+	t.Run("fails when the loans cannot be listed", func(t *testing.T) {
+		documents := new(MockDocumentService)
+		documents.On("IssuePayoffCertificates", ctx).Return(nil, errors.New("connection reset"))
+
+		err := batch.NewPayoffCertificateJob(documents, logger).Run(ctx)
+
+		assert.ErrorContains(t, err, "connection reset")
+	})
+}
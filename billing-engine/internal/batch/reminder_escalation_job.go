@@ -0,0 +1,48 @@
+package batch
+
+import (
+	"billing-engine/internal/domain/collections"
+	"billing-engine/internal/jobs"
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// ReminderEscalationJob walks past-due loans up the reminder ladder. Like
+// the assignment job it reads the stored days past due, and it runs after
+// the assignment job so call and letter tasks carry the new collector.
+type ReminderEscalationJob struct {
+	collectionsService collections.Service
+	logger             *slog.Logger
+}
+
+func NewReminderEscalationJob(collectionsSvc collections.Service, logger *slog.Logger) *ReminderEscalationJob {
+	if collectionsSvc == nil || logger == nil {
+		panic("ReminderEscalationJob dependencies cannot be nil")
+	}
+	return &ReminderEscalationJob{
+		collectionsService: collectionsSvc,
+		logger:             logger.With("job", "ReminderEscalation"),
+	}
+}
+
+func (j *ReminderEscalationJob) Run(ctx context.Context) error {
+	startTime := time.Now()
+	j.logger.InfoContext(ctx, "Starting daily reminder escalation job.")
+
+	report, err := j.collectionsService.Escalate(ctx)
+	if err != nil {
+		j.logger.ErrorContext(ctx, "Reminder escalation job failed.", slog.Any("error", err))
+		return fmt.Errorf("reminder escalation job failed: %w", err)
+	}
+
+	jobs.ProgressFrom(ctx).Done(report.Reminders + report.Tasks + report.Reset + report.Stale)
+	j.logger.InfoContext(ctx, "Reminder escalation job finished.",
+		slog.Int("reminders", report.Reminders),
+		slog.Int("tasks", report.Tasks),
+		slog.Int("reset", report.Reset),
+		slog.Int("stale", report.Stale),
+		slog.Duration("duration", time.Since(startTime)))
+	return nil
+}
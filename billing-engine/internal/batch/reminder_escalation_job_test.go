@@ -0,0 +1,37 @@
+package batch_test
+
+import (
+	"billing-engine/internal/batch"
+	"billing-engine/internal/domain/collections"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReminderEscalationJobRun(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	t.Run("escalates the past-due book", func(t *testing.T) {
+		service := new(MockCollectionsService)
+		service.On("Escalate", ctx).Return(&collections.EscalationReport{Reminders: 3, Tasks: 1, Reset: 2}, nil)
+
+		err := batch.NewReminderEscalationJob(service, logger).Run(ctx)
+
+		assert.NoError(t, err)
+		service.AssertExpectations(t)
+	})
+
+	t.Run("returns service error", func(t *testing.T) {
+		service := new(MockCollectionsService)
+		service.On("Escalate", ctx).Return(nil, errors.New("database error"))
+
+		err := batch.NewReminderEscalationJob(service, logger).Run(ctx)
+
+		assert.ErrorContains(t, err, "database error")
+	})
+}
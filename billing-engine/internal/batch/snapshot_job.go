@@ -0,0 +1,50 @@
+package batch
+
+import (
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/jobs"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// LoanSnapshotJob records the end-of-day state of every loan. It is meant to
+// run shortly before midnight so that the snapshot date matches the business
+// day it describes.
+type LoanSnapshotJob struct {
+	snapshotService loan.SnapshotService
+	clock           clock.Clock
+	logger          *slog.Logger
+}
+
+// NewLoanSnapshotJob builds the job. The clock decides the business day
+// being recorded.
+func NewLoanSnapshotJob(snapshotSvc loan.SnapshotService, clk clock.Clock, logger *slog.Logger) *LoanSnapshotJob {
+	if snapshotSvc == nil || clk == nil || logger == nil {
+		panic("LoanSnapshotJob dependencies cannot be nil")
+	}
+	return &LoanSnapshotJob{
+		snapshotService: snapshotSvc,
+		clock:           clk,
+		logger:          logger.With("job", "LoanSnapshot"),
+	}
+}
+
+func (j *LoanSnapshotJob) Run(ctx context.Context) error {
+	startTime := time.Now()
+	j.logger.InfoContext(ctx, "Starting daily loan snapshot job.")
+
+	written, err := j.snapshotService.TakeSnapshots(ctx, j.clock.Now().UTC())
+	if err != nil {
+		j.logger.ErrorContext(ctx, "Loan snapshot job failed.", slog.Any("error", err))
+		return fmt.Errorf("loan snapshot job failed: %w", err)
+	}
+
+	jobs.ProgressFrom(ctx).Done(int(written))
+	j.logger.InfoContext(ctx, "Loan snapshot job finished.",
+		slog.Int64("loans", written),
+		slog.Duration("duration", time.Since(startTime)))
+	return nil
+}
@@ -0,0 +1,72 @@
+package batch_test
+
+import (
+	"billing-engine/internal/batch"
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockSnapshotService struct {
+	mock.Mock
+}
+
+func (m *MockSnapshotService) TakeSnapshots(ctx context.Context, date time.Time) (int64, error) {
+	args := m.Called(ctx, date)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockSnapshotService) LoanHistory(ctx context.Context, loanID int64, from, to time.Time) ([]loan.Snapshot, error) {
+	args := m.Called(ctx, loanID, from, to)
+	return args.Get(0).([]loan.Snapshot), args.Error(1)
+}
+
+func (m *MockSnapshotService) PortfolioAt(ctx context.Context, date time.Time) (*loan.PortfolioReport, error) {
+	args := m.Called(ctx, date)
+	return args.Get(0).(*loan.PortfolioReport), args.Error(1)
+}
+
+func (m *MockSnapshotService) StreamPortfolio(ctx context.Context, date time.Time, afterLoanID int64, fn func(loan.Snapshot) error) error {
+	args := m.Called(ctx, date, afterLoanID)
+	snapshots, _ := args.Get(0).([]loan.Snapshot)
+	for _, s := range snapshots {
+		if err := fn(s); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+func TestLoanSnapshotJobRun(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	now := time.Date(2025, 1, 6, 23, 50, 0, 0, time.UTC)
+	clk := clock.NewFake(now)
+
+	t.Run("snapshots today's book", func(t *testing.T) {
+		service := new(MockSnapshotService)
+		service.On("TakeSnapshots", ctx, now).Return(int64(3), nil)
+
+		err := batch.NewLoanSnapshotJob(service, clk, logger).Run(ctx)
+
+		assert.NoError(t, err)
+		service.AssertExpectations(t)
+	})
+
+	t.Run("returns service error", func(t *testing.T) {
+		service := new(MockSnapshotService)
+		service.On("TakeSnapshots", ctx, now).Return(int64(0), errors.New("database error"))
+
+		err := batch.NewLoanSnapshotJob(service, clk, logger).Run(ctx)
+
+		assert.ErrorContains(t, err, "database error")
+	})
+}
@@ -0,0 +1,44 @@
+package batch
+
+import (
+	"billing-engine/internal/domain/summary"
+	"billing-engine/internal/jobs"
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// SummaryRebuildJob recomputes every customer loan summary, catching up with
+// changes the projector has no event for.
+type SummaryRebuildJob struct {
+	summaryService summary.Service
+	logger         *slog.Logger
+}
+
+func NewSummaryRebuildJob(summarySvc summary.Service, logger *slog.Logger) *SummaryRebuildJob {
+	if summarySvc == nil || logger == nil {
+		panic("SummaryRebuildJob dependencies cannot be nil")
+	}
+	return &SummaryRebuildJob{
+		summaryService: summarySvc,
+		logger:         logger.With("job", "SummaryRebuild"),
+	}
+}
+
+func (j *SummaryRebuildJob) Run(ctx context.Context) error {
+	startTime := time.Now()
+	j.logger.InfoContext(ctx, "Starting customer summary rebuild job.")
+
+	rows, err := j.summaryService.Rebuild(ctx)
+	if err != nil {
+		j.logger.ErrorContext(ctx, "Customer summary rebuild job failed.", slog.Any("error", err))
+		return fmt.Errorf("customer summary rebuild job failed: %w", err)
+	}
+
+	jobs.ProgressFrom(ctx).Done(int(rows))
+	j.logger.InfoContext(ctx, "Customer summary rebuild job finished.",
+		slog.Int64("rows", rows),
+		slog.Duration("duration", time.Since(startTime)))
+	return nil
+}
@@ -0,0 +1,61 @@
+package batch_test
+
+import (
+	"billing-engine/internal/batch"
+	"billing-engine/internal/domain/summary"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockSummaryService struct {
+	mock.Mock
+}
+
+func (m *MockSummaryService) GetSummary(ctx context.Context, customerID int64) (*summary.CustomerLoanSummary, error) {
+	args := m.Called(ctx, customerID)
+	s, _ := args.Get(0).(*summary.CustomerLoanSummary)
+	return s, args.Error(1)
+}
+
+func (m *MockSummaryService) Refresh(ctx context.Context, customerID int64) error {
+	return m.Called(ctx, customerID).Error(0)
+}
+
+func (m *MockSummaryService) RefreshForLoan(ctx context.Context, loanID int64) error {
+	return m.Called(ctx, loanID).Error(0)
+}
+
+func (m *MockSummaryService) Rebuild(ctx context.Context) (int64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func TestSummaryRebuildJobRun(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	t.Run("rebuilds every summary", func(t *testing.T) {
+		service := new(MockSummaryService)
+		service.On("Rebuild", ctx).Return(int64(12), nil)
+
+		err := batch.NewSummaryRebuildJob(service, logger).Run(ctx)
+
+		assert.NoError(t, err)
+		service.AssertExpectations(t)
+	})
+
+	t.Run("returns service error", func(t *testing.T) {
+		service := new(MockSummaryService)
+		service.On("Rebuild", ctx).Return(int64(0), errors.New("database error"))
+
+		err := batch.NewSummaryRebuildJob(service, logger).Run(ctx)
+
+		assert.ErrorContains(t, err, "database error")
+	})
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/spf13/viper"
+)
+
+// EnvPrefix starts the environment variable of every setting, which is the
+// key upper-cased with its dots as underscores: server.readTimeout is set
+// by BILLING_SERVER_READTIMEOUT. The name without the prefix,
+// SERVER_READTIMEOUT, is still read when the prefixed one is not set.
+// Maps and lists, such as payments.minorUnits or rabbitmq.topology.queues,
+// take JSON: BILLING_PAYMENTS_MINORUNITS='{"IDR":2,"USD":2}'.
+const EnvPrefix = "BILLING"
+
+// ConfigFileEnv names the file LoadConfig reads instead of looking for
+// config.yml in its path. Set to "none", no file is read at all and the
+// service is configured by its defaults and the environment alone, as in a
+// container with nothing mounted.
+const ConfigFileEnv = EnvPrefix + "_CONFIG_FILE"
+
+const noConfigFile = "none"
+
+// EnvName returns the environment variable that sets key.
+func EnvName(key string) string {
+	return EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// bindEnv binds every setting to its two environment variables, prefixed
+// first. AutomaticEnv would only see the keys viper already has from a
+// default or the config file, leaving settings such as server.auth.issuer
+// out of an environment-only configuration, and would let the unprefixed
+// name win.
+func bindEnv(fields map[string]configField) error {
+	for name, field := range fields {
+		if field.typ.Kind() == reflect.Struct && field.typ != durationType {
+			continue
+		}
+		unprefixed := strings.ToUpper(strings.ReplaceAll(name, ".", "_"))
+		if err := viper.BindEnv(name, EnvName(name), unprefixed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readConfigFile reads the file ConfigFileEnv names, or config.yml in path
+// when it names none. Only a config.yml that is not there is no error.
+func readConfigFile(path string) error {
+	switch file := os.Getenv(ConfigFileEnv); file {
+	case noConfigFile:
+		return nil
+	case "":
+		viper.AddConfigPath(path)
+		viper.SetConfigName("config")
+		viper.SetConfigType("yml")
+	default:
+		viper.SetConfigFile(file)
+	}
+	return viper.ReadInConfig()
+}
+
+// jsonValue decodes the JSON an environment variable gives a map or list
+// setting. Anything else, including a comma-separated list of strings, is
+// passed on unchanged.
+func jsonValue(from, to reflect.Type, data any) (any, error) {
+	if from.Kind() != reflect.String || (to.Kind() != reflect.Map && to.Kind() != reflect.Slice) {
+		return data, nil
+	}
+	return decodeJSON(data.(string))
+}
+
+func decodeJSON(s string) (any, error) {
+	trimmed := strings.TrimSpace(s)
+	if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+		return s, nil
+	}
+	var value any
+	if err := json.Unmarshal([]byte(trimmed), &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+var decodeHook = viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+	jsonValue,
+	mapstructure.StringToTimeDurationHookFunc(),
+	mapstructure.StringToSliceHookFunc(","),
+))
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvName(t *testing.T) {
+	assert.Equal(t, "BILLING_SERVER_READTIMEOUT", EnvName("server.readTimeout"))
+	assert.Equal(t, "BILLING_RABBITMQ_TOPOLOGY_QUEUES", EnvName("rabbitmq.topology.queues"))
+}
+
+func TestLoadConfigFromEnvironmentOnly(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	t.Setenv(ConfigFileEnv, "none")
+	t.Setenv("SERVER_PORT", "8081")
+	t.Setenv("BILLING_SERVER_PORT", "8082")
+	t.Setenv("BILLING_SERVER_READTIMEOUT", "20s")
+	t.Setenv("BILLING_SERVER_AUTH_ISSUER", "billing-engine")
+	t.Setenv("BILLING_STORAGE_ENDPOINT", "http://minio:9000")
+	t.Setenv("BILLING_STORAGE_BUCKET", "attachments")
+	t.Setenv("NOTIFY_JWTSECRET", "shared-secret")
+	t.Setenv("BILLING_PAYMENTS_CURRENCY", "EUR")
+	t.Setenv("BILLING_PAYMENTS_MINORUNITS", `{"EUR": 2, "JPY": 0}`)
+	t.Setenv("BILLING_BATCH_TIMEZONES", `{"LoanSnapshot": "Asia/Jakarta"}`)
+	t.Setenv("BILLING_BATCH_WATCHDOG_MAXRUNTIMES", `{"LoanSnapshot": "45m"}`)
+	t.Setenv("BILLING_CREDIT_LIMITS", `{"A": 5000000, "B": 2000000}`)
+	t.Setenv("BILLING_DOCUMENTS_RETENTION", `{"ID_COPY": "43800h"}`)
+	t.Setenv("BILLING_RABBITMQ_TOPOLOGY_EXCHANGES", `[{"name": "billing", "type": "topic"}]`)
+	t.Setenv("BILLING_RABBITMQ_TOPOLOGY_QUEUES", `[{"name": "audit", "messageTTL": "30s", "bindings": [{"exchange": "billing", "routingKeys": ["loan.*"]}]}]`)
+
+	// The config file in the repository root is not read.
+	cfg, err := LoadConfig("../..")
+	require.NoError(t, err)
+	assert.Empty(t, viper.ConfigFileUsed())
+	assert.Equal(t, time.Duration(30), cfg.Batch.DelinquencyUpdateTimeout)
+
+	assert.Equal(t, 8082, cfg.Server.Port)
+	assert.Equal(t, 20*time.Second, cfg.Server.ReadTimeout)
+	assert.Equal(t, "billing-engine", cfg.Server.Auth.Issuer)
+	assert.Equal(t, "attachments", cfg.Storage.Bucket)
+	assert.Equal(t, "shared-secret", cfg.Notify.JWTSecret)
+	digits, err := cfg.Payments.MinorUnitDigits()
+	require.NoError(t, err)
+	assert.Equal(t, 2, digits)
+	assert.Equal(t, map[string]string{"LoanSnapshot": "Asia/Jakarta"}, cfg.Batch.Timezones)
+	assert.Equal(t, map[string]time.Duration{"LoanSnapshot": 45 * time.Minute}, cfg.Batch.Watchdog.MaxRuntimes)
+	assert.Equal(t, map[string]float64{"A": 5000000, "B": 2000000}, cfg.Credit.Limits)
+	assert.Equal(t, map[string]time.Duration{"ID_COPY": 5 * 365 * 24 * time.Hour}, cfg.Documents.Retention)
+	assert.Equal(t, TopologyConfig{
+		Exchanges: []ExchangeConfig{{Name: "billing", Type: "topic"}},
+		Queues: []QueueConfig{{
+			Name:       "audit",
+			MessageTTL: 30 * time.Second,
+			Bindings:   []BindingConfig{{Exchange: "billing", RoutingKeys: []string{"loan.*"}}},
+		}},
+	}, cfg.RabbitMQ.Topology)
+}
+
+func TestLoadConfigFromEnvironmentReportsBadValues(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	t.Setenv(ConfigFileEnv, "none")
+	t.Setenv("BILLING_CREDIT_LIMITS", `{"A": 5000000`)
+	t.Setenv("BILLING_BATCH_WATCHDOG_MAXRUNTIMES", `{"LoanSnapshot": 45}`)
+
+	_, err := LoadConfig(".")
+	problems := problemsOf(t, err)
+	assert.Contains(t, problems, "batch.watchdog.maxRuntimes.LoanSnapshot: 45 has no unit and would be read as nanoseconds, write it as 45s for seconds or with another unit such as m or h")
+	assert.Contains(t, problems, "credit.limits: is not valid JSON: unexpected end of JSON input")
+}
+
+func TestLoadConfigFileFromEnvironment(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	file := filepath.Join(t.TempDir(), "billing.yml")
+	require.NoError(t, os.WriteFile(file, []byte("server:\n  port: 8083\n"), 0o644))
+	t.Setenv(ConfigFileEnv, file)
+
+	cfg, err := LoadConfig("../..")
+	require.NoError(t, err)
+	assert.Equal(t, file, viper.ConfigFileUsed())
+	assert.Equal(t, 8083, cfg.Server.Port)
+
+	viper.Reset()
+	t.Setenv(ConfigFileEnv, filepath.Join(t.TempDir(), "missing.yml"))
+	_, err = LoadConfig("../..")
+	assert.Error(t, err)
+}
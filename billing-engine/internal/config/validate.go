@@ -0,0 +1,535 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/spf13/viper"
+)
+
+// secondsKeys are the batch job timeouts. Unlike every other duration they
+// are whole seconds, delinquencyTimeout: 1800 being half an hour, so a unit
+// must not be given.
+var secondsKeys = map[string]bool{
+	"batch.delinquencytimeout":      true,
+	"batch.snapshottimeout":         true,
+	"batch.summarytimeout":          true,
+	"batch.integritytimeout":        true,
+	"batch.partitiontimeout":        true,
+	"directdebit.timeout":           true,
+	"collections.timeout":           true,
+	"collections.escalationtimeout": true,
+	"retention.timeout":             true,
+	"documents.timeout":             true,
+	"documents.retentiontimeout":    true,
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Problem is one thing wrong with a configuration. Key is spelled as in
+// config.yml, such as batch.delinquencyTimeout, except that unknown keys
+// are lower case as viper reports them, and empty when the problem is not
+// about one setting.
+type Problem struct {
+	Key     string
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Key == "" {
+		return p.Message
+	}
+	return p.Key + ": " + p.Message
+}
+
+// ValidationError lists everything wrong with a configuration, so one
+// attempt to start shows all of it.
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	if len(e.Problems) == 1 {
+		b.WriteString("invalid configuration, 1 problem:")
+	} else {
+		fmt.Fprintf(&b, "invalid configuration, %d problems:", len(e.Problems))
+	}
+	for _, p := range e.Problems {
+		b.WriteString("\n  ")
+		b.WriteString(p.String())
+	}
+	return b.String()
+}
+
+// Validate checks the values of c: required settings, ranges and the names
+// and schedules the service parses at startup. LoadConfig runs it, after
+// checking the keys and units of what it read.
+func (c *Config) Validate() error {
+	if problems := c.problems(); len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+type problemList []Problem
+
+func (l *problemList) add(key, format string, args ...any) {
+	*l = append(*l, Problem{Key: key, Message: fmt.Sprintf(format, args...)})
+}
+
+func (l *problemList) port(key string, port int) {
+	if port < 1 || port > 65535 {
+		l.add(key, "%d is not a port, want 1 to 65535", port)
+	}
+}
+
+func (l *problemList) atLeast(key string, value, min int64) {
+	if value < min {
+		l.add(key, "must be at least %d, got %d", min, value)
+	}
+}
+
+func (l *problemList) notNegative(key string, d time.Duration) {
+	if d < 0 {
+		l.add(key, "must not be negative, got %s", d)
+	}
+}
+
+func (l *problemList) oneOf(key, value string, allowed ...string) {
+	if value == "" {
+		return
+	}
+	for _, a := range allowed {
+		if strings.EqualFold(value, a) {
+			return
+		}
+	}
+	l.add(key, "unknown value %q, want %s", value, strings.Join(allowed, ", "))
+}
+
+func (l *problemList) schedule(key, spec string) {
+	if spec == "" {
+		return
+	}
+	if _, err := cron.ParseStandard(spec); err != nil {
+		l.add(key, "schedule %q: %v", spec, err)
+	}
+}
+
+func (l *problemList) timezone(key, name string) {
+	if name == "" {
+		return
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		l.add(key, "unknown timezone %q", name)
+	}
+}
+
+func (c *Config) problems() []Problem {
+	var l problemList
+
+	l.port("server.port", c.Server.Port)
+	l.notNegative("server.readTimeout", c.Server.ReadTimeout)
+	l.notNegative("server.writeTimeout", c.Server.WriteTimeout)
+	l.notNegative("server.idleTimeout", c.Server.IdleTimeout)
+	if c.Server.RateLimit.Enabled {
+		if c.Server.RateLimit.RPS <= 0 {
+			l.add("server.rateLimit.rps", "must be above 0 while the rate limit is enabled, got %g", c.Server.RateLimit.RPS)
+		}
+		l.atLeast("server.rateLimit.burst", int64(c.Server.RateLimit.Burst), 1)
+	}
+	if c.Server.TLS.CertFile != "" && c.Server.TLS.KeyFile == "" {
+		l.add("server.tls.keyFile", "required when server.tls.certFile is set")
+	}
+	if c.Server.TLS.KeyFile != "" && c.Server.TLS.CertFile == "" {
+		l.add("server.tls.certFile", "required when server.tls.keyFile is set")
+	}
+	l.oneOf("server.tls.clientAuth", c.Server.TLS.ClientAuth, "require", "optional", "none")
+	l.atLeast("server.bodyLimit.defaultBytes", c.Server.BodyLimit.DefaultBytes, 0)
+	if o := c.Server.SLO.Objective; o <= 0 || o >= 1 {
+		l.add("server.slo.objective", "must be between 0 and 1, such as 0.995, got %g", o)
+	}
+	if sunset := c.Server.API.LegacySunset; sunset != "" {
+		if _, err := time.Parse(time.DateOnly, sunset); err != nil {
+			l.add("server.api.legacySunset", "%q is not a date such as 2026-06-30", sunset)
+		}
+	}
+	for _, route := range slices.Sorted(maps.Keys(c.Server.API.DeprecatedRoutes)) {
+		if sunset := c.Server.API.DeprecatedRoutes[route]; sunset != "" {
+			if _, err := time.Parse(time.DateOnly, sunset); err != nil {
+				l.add("server.api.deprecatedRoutes", "%s: %q is not a date such as 2026-06-30", route, sunset)
+			}
+		}
+	}
+
+	switch driver := strings.ToLower(strings.TrimSpace(c.Database.Driver)); driver {
+	case "", "postgres":
+		if c.Database.URL == "" {
+			l.add("database.url", "required with the postgres driver")
+		}
+	case "sqlite":
+		if c.Database.Path == "" {
+			l.add("database.path", "required with the sqlite driver")
+		}
+	default:
+		l.add("database.driver", "unknown value %q, want postgres, sqlite", c.Database.Driver)
+	}
+	l.oneOf("database.tenantEnforcement", c.Database.TenantEnforcement, "application", "rls")
+	if strings.EqualFold(c.Database.TenantEnforcement, "rls") && strings.EqualFold(strings.TrimSpace(c.Database.Driver), "sqlite") {
+		l.add("database.tenantEnforcement", "rls needs the postgres driver")
+	}
+	l.oneOf("logger.level", c.Logger.Level, "debug", "info", "warn", "error")
+	l.port("metrics.port", c.Metrics.Port)
+	l.atLeast("loanDefaults.termWeeks", int64(c.Loan.TermWeeks), 1)
+
+	l.schedule("batch.delinquencySchedule", c.Batch.DelinquencyUpdateSchedule)
+	l.schedule("batch.snapshotSchedule", c.Batch.SnapshotSchedule)
+	l.schedule("batch.summarySchedule", c.Batch.SummarySchedule)
+	l.schedule("batch.integritySchedule", c.Batch.IntegritySchedule)
+	l.schedule("batch.partitionSchedule", c.Batch.PartitionSchedule)
+	l.schedule("collections.schedule", c.Collections.Schedule)
+	l.schedule("collections.escalationSchedule", c.Collections.EscalationSchedule)
+	l.atLeast("collections.reminderWindowDays", int64(c.Collections.ReminderWindowDays), 0)
+	if c.DirectDebit.Enabled {
+		l.schedule("directDebit.schedule", c.DirectDebit.Schedule)
+	}
+	if c.Retention.Enabled {
+		l.schedule("retention.schedule", c.Retention.Schedule)
+	}
+	l.schedule("documents.schedule", c.Documents.Schedule)
+	l.schedule("documents.retentionSchedule", c.Documents.RetentionSchedule)
+	for _, timeout := range []struct {
+		key     string
+		seconds time.Duration
+	}{
+		{"batch.delinquencyTimeout", c.Batch.DelinquencyUpdateTimeout},
+		{"batch.snapshotTimeout", c.Batch.SnapshotTimeout},
+		{"batch.summaryTimeout", c.Batch.SummaryTimeout},
+		{"batch.integrityTimeout", c.Batch.IntegrityTimeout},
+		{"batch.partitionTimeout", c.Batch.PartitionTimeout},
+		{"directDebit.timeout", c.DirectDebit.Timeout},
+		{"collections.timeout", c.Collections.Timeout},
+		{"collections.escalationTimeout", c.Collections.EscalationTimeout},
+		{"retention.timeout", c.Retention.Timeout},
+		{"documents.timeout", c.Documents.Timeout},
+		{"documents.retentionTimeout", c.Documents.RetentionTimeout},
+	} {
+		if timeout.seconds < 0 {
+			l.add(timeout.key, "must not be negative, got %d", int64(timeout.seconds))
+		}
+	}
+	l.atLeast("batch.partitionMonthsAhead", int64(c.Batch.PartitionMonthsAhead), 0)
+	l.timezone("batch.timezone", c.Batch.Timezone)
+	for _, job := range slices.Sorted(maps.Keys(c.Batch.Timezones)) {
+		l.timezone("batch.timezones."+job, c.Batch.Timezones[job])
+	}
+	l.oneOf("batch.overlap", c.Batch.Overlap, "skip", "queue", "allow")
+	for _, job := range slices.Sorted(maps.Keys(c.Batch.Overlaps)) {
+		l.oneOf("batch.overlaps."+job, c.Batch.Overlaps[job], "skip", "queue", "allow")
+	}
+	if c.Batch.Watchdog.Enabled && c.Batch.Watchdog.Interval <= 0 {
+		l.add("batch.watchdog.interval", "must be above 0 while the watchdog is enabled, got %s", c.Batch.Watchdog.Interval)
+	}
+	l.notNegative("batch.watchdog.grace", c.Batch.Watchdog.Grace)
+
+	l.port("rabbitmq.port", c.RabbitMQ.Port)
+	if c.RabbitMQ.ExchangeName == "" {
+		l.add("rabbitmq.exchangeName", "required")
+	}
+	l.atLeast("events.bufferSize", int64(c.Events.BufferSize), 1)
+	l.atLeast("events.replaySize", int64(c.Events.ReplaySize), 0)
+	l.atLeast("events.publishBatchSize", int64(c.Events.PublishBatchSize), 1)
+	l.oneOf("customers.duplicateCheck", c.Customers.DuplicateCheck, "off", "exact", "fuzzy")
+	l.atLeast("import.chunkSize", int64(c.Import.ChunkSize), 1)
+	l.atLeast("import.maxRows", int64(c.Import.MaxRows), 1)
+	l.atLeast("bulk.maxStreamRows", int64(c.Bulk.MaxStreamRows), 0)
+	l.atLeast("jobs.workers", int64(c.Jobs.Workers), 1)
+	l.atLeast("jobs.queueSize", int64(c.Jobs.QueueSize), 1)
+	l.atLeast("jobs.maxAttempts", int64(c.Jobs.MaxAttempts), 1)
+	if c.Jobs.Lease <= 0 {
+		l.add("jobs.lease", "must be above 0, got %s", c.Jobs.Lease)
+	}
+	if c.Jobs.PollInterval <= 0 {
+		l.add("jobs.pollInterval", "must be above 0, got %s", c.Jobs.PollInterval)
+	}
+	l.notNegative("cache.outstandingTTL", c.Cache.OutstandingTTL)
+	l.notNegative("cache.tenantSettingsTTL", c.Cache.TenantSettingsTTL)
+	if c.Storage.Endpoint != "" && c.Storage.Bucket == "" {
+		l.add("storage.bucket", "required when storage.endpoint is set")
+	}
+	// A presigned link cannot be valid for longer than a week.
+	if c.Documents.LinkTTL < time.Second || c.Documents.LinkTTL > 7*24*time.Hour {
+		l.add("documents.linkTTL", "must be between 1s and 168h, got %s", c.Documents.LinkTTL)
+	}
+	if c.Documents.UploadLinkTTL < time.Second || c.Documents.UploadLinkTTL > 7*24*time.Hour {
+		l.add("documents.uploadLinkTTL", "must be between 1s and 168h, got %s", c.Documents.UploadLinkTTL)
+	}
+	if c.Documents.Lookback <= 0 {
+		l.add("documents.lookback", "must be above 0, got %s", c.Documents.Lookback)
+	}
+	l.atLeast("documents.batchSize", int64(c.Documents.BatchSize), 1)
+	if c.Payments.Tolerance < 0 {
+		l.add("payments.tolerance", "must not be negative, got %g", c.Payments.Tolerance)
+	}
+	if c.Payments.Currency == "" {
+		l.add("payments.currency", "required")
+	}
+	return l
+}
+
+// checkKeys looks at what viper read before it is decoded: keys no setting
+// has, which viper would silently ignore, and durations written without a
+// unit, which it would read as nanoseconds.
+func checkKeys(fields map[string]configField) []Problem {
+	keys := viper.AllKeys()
+	sort.Strings(keys)
+	var l problemList
+	for _, key := range keys {
+		field, ok := fields[key]
+		if !ok {
+			field, ok = elementField(fields, key)
+		}
+		if !ok {
+			if suggestion := closestKey(fields, key); suggestion != "" {
+				l.add(key, "unknown setting, did you mean %s?", suggestion)
+			} else {
+				l.add(key, "unknown setting")
+			}
+			continue
+		}
+		raw := viper.Get(key)
+		if s, ok := raw.(string); ok && (field.typ.Kind() == reflect.Map || field.typ.Kind() == reflect.Slice) {
+			value, err := decodeJSON(s)
+			if err != nil {
+				l.add(field.key, "is not valid JSON: %v", err)
+				continue
+			}
+			raw = value
+		}
+		switch {
+		case raw == nil:
+		case field.typ.Kind() == reflect.Map && field.typ.Elem() == durationType:
+			entries, _ := raw.(map[string]any)
+			for _, entry := range slices.Sorted(maps.Keys(entries)) {
+				if msg := checkDuration(entries[entry]); msg != "" {
+					l.add(field.key+"."+entry, "%s", msg)
+				}
+			}
+		case secondsKeys[key]:
+			if msg := checkSeconds(raw); msg != "" {
+				l.add(field.key, "%s", msg)
+			}
+		case field.typ == durationType:
+			if msg := checkDuration(raw); msg != "" {
+				l.add(field.key, "%s", msg)
+			}
+		}
+	}
+	return l
+}
+
+// decodeProblems turns what viper failed to decode into problems, leaving
+// out the settings checkKeys already reported.
+func decodeProblems(err error, reported []Problem) []Problem {
+	var l problemList
+	for _, leaf := range leafErrors(err) {
+		msg := leaf.Error()
+		match := decodeErrorKey.FindStringSubmatch(msg)
+		if match == nil {
+			l.add("", "%s", msg)
+			continue
+		}
+		key := match[1]
+		reason := strings.TrimPrefix(strings.Replace(msg, " '"+key+"'", "", 1), "error decoding: ")
+		if !slices.ContainsFunc(reported, func(p Problem) bool { return strings.EqualFold(p.Key, key) }) {
+			l.add(key, "%s", reason)
+		}
+	}
+	return l
+}
+
+// decodeErrorKey finds the setting in a mapstructure error, which quotes it
+// as in "cannot parse 'server.port' as int".
+var decodeErrorKey = regexp.MustCompile(`'([A-Za-z0-9_.]+)'`)
+
+// leafErrors unwraps the errors mapstructure joins, one per setting.
+func leafErrors(err error) []error {
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		if inner := errors.Unwrap(err); inner != nil && !strings.HasPrefix(err.Error(), "error decoding '") {
+			return leafErrors(inner)
+		}
+		return []error{err}
+	}
+	var leaves []error
+	for _, e := range joined.Unwrap() {
+		leaves = append(leaves, leafErrors(e)...)
+	}
+	return leaves
+}
+
+// configField is a setting by its key as config.yml spells it.
+type configField struct {
+	key string
+	typ reflect.Type
+}
+
+// configFields lists the settings of Config by their lower-cased keys, as
+// viper reports them, down to the structs they are grouped in.
+func configFields() map[string]configField {
+	fields := make(map[string]configField)
+	collectFields(reflect.TypeOf(Config{}), "", fields)
+	return fields
+}
+
+func collectFields(t reflect.Type, prefix string, fields map[string]configField) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("mapstructure")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		key := tag
+		if prefix != "" {
+			key = prefix + "." + tag
+		}
+		fields[strings.ToLower(key)] = configField{key: key, typ: f.Type}
+		if f.Type.Kind() == reflect.Struct && f.Type != durationType {
+			collectFields(f.Type, key, fields)
+		}
+	}
+}
+
+// elementField finds the map or list setting key is an entry of, such as
+// batch.watchdog.maxRuntimes for batch.watchdog.maxruntimes.loansnapshot. A map
+// entry is returned with the map's element type, keyed by the entry.
+func elementField(fields map[string]configField, key string) (configField, bool) {
+	for prefix := key; ; {
+		i := strings.LastIndex(prefix, ".")
+		if i < 0 {
+			return configField{}, false
+		}
+		prefix = prefix[:i]
+		field, ok := fields[prefix]
+		if !ok {
+			continue
+		}
+		switch field.typ.Kind() {
+		case reflect.Map:
+			return configField{key: field.key + key[len(prefix):], typ: field.typ.Elem()}, true
+		case reflect.Slice:
+			return configField{key: key, typ: field.typ.Elem()}, true
+		}
+		return configField{}, false
+	}
+}
+
+func checkDuration(raw any) string {
+	switch v := raw.(type) {
+	case time.Duration, nil:
+		return ""
+	case string:
+		s := strings.TrimSpace(v)
+		if n, err := strconv.ParseFloat(s, 64); err == nil {
+			if n == 0 {
+				return ""
+			}
+			return fmt.Sprintf("%q has no unit, write it as %ss for seconds or with another unit such as m or h", v, s)
+		}
+		if _, err := time.ParseDuration(s); err != nil {
+			return fmt.Sprintf("%q is not a duration, such as 30s, 5m or 1h", v)
+		}
+		return ""
+	}
+	if isNumber(raw) {
+		if reflect.ValueOf(raw).IsZero() {
+			return ""
+		}
+		return fmt.Sprintf("%v has no unit and would be read as nanoseconds, write it as %vs for seconds or with another unit such as m or h", raw, raw)
+	}
+	return fmt.Sprintf("%v is not a duration, such as 30s, 5m or 1h", raw)
+}
+
+func checkSeconds(raw any) string {
+	if isNumber(raw) {
+		return ""
+	}
+	s, ok := raw.(string)
+	if !ok {
+		return fmt.Sprintf("%v is not a number of seconds", raw)
+	}
+	s = strings.TrimSpace(s)
+	if _, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return ""
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return fmt.Sprintf("is a number of seconds without a unit, write %d for %q", int64(d/time.Second), s)
+	}
+	return fmt.Sprintf("%q is not a number of seconds", s)
+}
+
+func isNumber(v any) bool {
+	if v == nil {
+		return false
+	}
+	switch reflect.TypeOf(v).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// closestKey suggests the setting a misspelt key was probably meant to be:
+// one in the same section whose last part is at most a third different.
+func closestKey(fields map[string]configField, key string) string {
+	section, name := "", key
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		section, name = key[:i], key[i+1:]
+	}
+	best, bestDistance := "", len(name)/3+1
+	for candidate, field := range fields {
+		candidateSection, candidateName := "", candidate
+		if i := strings.LastIndex(candidate, "."); i >= 0 {
+			candidateSection, candidateName = candidate[:i], candidate[i+1:]
+		}
+		if candidateSection != section {
+			continue
+		}
+		if d := editDistance(name, candidateName); d < bestDistance || (d == bestDistance && best != "" && field.key < best) {
+			best, bestDistance = field.key, d
+		}
+	}
+	return best
+}
+
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadConfigFile(t *testing.T, content string) (*Config, error) {
+	t.Helper()
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config.yml"), []byte(content), 0o644))
+	return LoadConfig(dir)
+}
+
+func problemsOf(t *testing.T, err error) []string {
+	t.Helper()
+	var validation *ValidationError
+	require.ErrorAs(t, err, &validation)
+	var problems []string
+	for _, p := range validation.Problems {
+		problems = append(problems, p.String())
+	}
+	return problems
+}
+
+func TestLoadConfigValidates(t *testing.T) {
+	t.Run("the checked-in config file is valid", func(t *testing.T) {
+		viper.Reset()
+		defer viper.Reset()
+		_, err := LoadConfig("../..")
+		assert.NoError(t, err)
+	})
+
+	t.Run("reports every problem together", func(t *testing.T) {
+		_, err := loadConfigFile(t, `
+server:
+  port: 70000
+  readTimeout: 15
+  writeTimout: 15s
+batch:
+  delinquencyTimeout: 30m
+  overlap: sometimes
+  timezone: Mars/Olympus
+jobs:
+  workers: 0
+`)
+		require.Error(t, err)
+		assert.ElementsMatch(t, []string{
+			`batch.delinquencyTimeout: is a number of seconds without a unit, write 1800 for "30m"`,
+			`server.readTimeout: 15 has no unit and would be read as nanoseconds, write it as 15s for seconds or with another unit such as m or h`,
+			`server.writetimout: unknown setting, did you mean server.writeTimeout?`,
+			`server.port: 70000 is not a port, want 1 to 65535`,
+			`batch.timezone: unknown timezone "Mars/Olympus"`,
+			`batch.overlap: unknown value "sometimes", want skip, queue, allow`,
+			`jobs.workers: must be at least 1, got 0`,
+		}, problemsOf(t, err))
+		assert.Contains(t, err.Error(), "invalid configuration, 7 problems:\n  ")
+	})
+
+	t.Run("checks durations in maps and from the environment", func(t *testing.T) {
+		t.Setenv("JOBS_LEASE", "120")
+		_, err := loadConfigFile(t, `
+batch:
+  watchdog:
+    maxRuntimes:
+      LoanSnapshot: 45
+`)
+		assert.ElementsMatch(t, []string{
+			`batch.watchdog.maxRuntimes.loansnapshot: 45 has no unit and would be read as nanoseconds, write it as 45s for seconds or with another unit such as m or h`,
+			`jobs.lease: "120" has no unit, write it as 120s for seconds or with another unit such as m or h`,
+		}, problemsOf(t, err))
+	})
+
+	t.Run("accepts unit-suffixed durations and whole seconds", func(t *testing.T) {
+		cfg, err := loadConfigFile(t, `
+server:
+  readTimeout: 1m30s
+batch:
+  delinquencyTimeout: 900
+  timezones:
+    LoanSnapshot: Asia/Jakarta
+`)
+		require.NoError(t, err)
+		assert.Equal(t, 90*time.Second, cfg.Server.ReadTimeout)
+		assert.Equal(t, time.Duration(900), cfg.Batch.DelinquencyUpdateTimeout)
+	})
+
+	t.Run("a value that cannot be decoded is reported", func(t *testing.T) {
+		_, err := loadConfigFile(t, `
+server:
+  port: eighty
+`)
+		problems := problemsOf(t, err)
+		require.Len(t, problems, 1)
+		assert.Equal(t, `server.port: cannot parse as int: strconv.ParseInt: parsing "eighty": invalid syntax`, problems[0])
+	})
+}
+
+func TestConfigValidate(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	cfg, err := LoadConfig(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, cfg.Validate())
+
+	cfg.Database.Driver = "sqlite"
+	cfg.Database.Path = ""
+	cfg.Database.TenantEnforcement = "rls"
+	cfg.Server.TLS.CertFile = "server.crt"
+	cfg.Server.SLO.Objective = 99.5
+	cfg.Server.API.LegacySunset = "30/06/2026"
+	cfg.Server.API.DeprecatedRoutes = map[string]string{"get /customers": "next year", "get /loans/{loanid}/delinquent": ""}
+	cfg.Batch.SnapshotSchedule = "every night"
+	cfg.DirectDebit.Enabled = true
+	cfg.DirectDebit.Schedule = "0 6 * *"
+	cfg.Storage.Endpoint = "http://minio:9000"
+	cfg.Documents.LinkTTL = 30 * 24 * time.Hour
+	cfg.Documents.UploadLinkTTL = 0
+	assert.Equal(t, []string{
+		"server.tls.keyFile: required when server.tls.certFile is set",
+		"server.slo.objective: must be between 0 and 1, such as 0.995, got 99.5",
+		`server.api.legacySunset: "30/06/2026" is not a date such as 2026-06-30`,
+		`server.api.deprecatedRoutes: get /customers: "next year" is not a date such as 2026-06-30`,
+		"database.path: required with the sqlite driver",
+		"database.tenantEnforcement: rls needs the postgres driver",
+		`batch.snapshotSchedule: schedule "every night": expected exactly 5 fields, found 2: [every night]`,
+		`directDebit.schedule: schedule "0 6 * *": expected exactly 5 fields, found 4: [0 6 * *]`,
+		"storage.bucket: required when storage.endpoint is set",
+		"documents.linkTTL: must be between 1s and 168h, got 720h0m0s",
+		"documents.uploadLinkTTL: must be between 1s and 168h, got 0s",
+	}, problemsOf(t, cfg.Validate()))
+}
+
+func TestClosestKey(t *testing.T) {
+	fields := configFields()
+
+	assert.Equal(t, "batch.delinquencyTimeout", closestKey(fields, "batch.delinquencytimout"))
+	assert.Equal(t, "batch.delinquencySchedule", closestKey(fields, "batch.delinquencyschedul"))
+	assert.Empty(t, closestKey(fields, "batch.somethingelse"))
+	assert.Empty(t, closestKey(fields, "server.delinquencytimeout"))
+}
@@ -0,0 +1,125 @@
+package agreement
+
+import (
+	"billing-engine/internal/domain/loan"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// HoldReason is the reason of the hold a loan created in workflow mode is
+// placed under until it is disbursed.
+const HoldReason = "Awaiting signature of the loan agreement"
+
+// Status is where the agreement's envelope stands at the e-signature
+// provider. An agreement is SENT until the customer signs it, which makes it
+// COMPLETED, or declines it, or the envelope is voided at the provider. The
+// last three are final.
+type Status string
+
+const (
+	StatusSent      Status = "SENT"
+	StatusCompleted Status = "COMPLETED"
+	StatusDeclined  Status = "DECLINED"
+	StatusVoided    Status = "VOIDED"
+)
+
+func ParseStatus(s string) (Status, error) {
+	status := Status(strings.ToUpper(strings.TrimSpace(s)))
+	switch status {
+	case StatusSent, StatusCompleted, StatusDeclined, StatusVoided:
+		return status, nil
+	}
+	return "", fmt.Errorf("unknown agreement status %q", s)
+}
+
+// Final reports whether the envelope can no longer change.
+func (s Status) Final() bool {
+	return s != StatusSent
+}
+
+// open reports whether the agreement still stands: sent or signed. A loan
+// has at most one open agreement.
+func (s Status) open() bool {
+	return s == StatusSent || s == StatusCompleted
+}
+
+// Agreement is the agreement of a loan created in workflow mode, sent to
+// the customer for signature through Provider as the envelope EnvelopeID.
+// DocumentID is the unsigned agreement kept with the loan's documents; it is
+// nil once retention removed it. HoldID is the hold the loan was placed
+// under while it awaits signature, released when it is disbursed; it is nil
+// when the loan was already on another hold. SignedAt is set when the
+// agreement is COMPLETED, and DisbursedAt once the loan is disbursed, which
+// only a COMPLETED agreement allows.
+type Agreement struct {
+	ID          int64
+	LoanID      int64
+	DocumentID  *int64
+	HoldID      *int64
+	Provider    string
+	EnvelopeID  string
+	Status      Status
+	SentBy      string
+	SentAt      time.Time
+	UpdatedAt   time.Time
+	SignedAt    *time.Time
+	DisbursedBy string
+	DisbursedAt *time.Time
+}
+
+// NewLoan holds the terms a loan is created in workflow mode with, as
+// loan.LoanService.CreateLoan takes them.
+type NewLoan struct {
+	CustomerID         int64
+	Principal          loan.Money
+	TermWeeks          int
+	AnnualInterestRate loan.Money
+	StartDate          time.Time
+	ExternalRef        string
+	PublicID           uuid.UUID
+	Product            string
+}
+
+// Signer is whom the agreement is sent to for signature.
+type Signer struct {
+	Name  string
+	Email string
+}
+
+// Envelope is an agreement to be signed. Reference is the loan's public ID,
+// which the provider keeps with the envelope.
+type Envelope struct {
+	LoanID      int64
+	Reference   string
+	Signer      Signer
+	FileName    string
+	ContentType string
+	Content     []byte
+}
+
+// StatusUpdate is what a provider's callback says about an envelope.
+type StatusUpdate struct {
+	EnvelopeID string
+	Status     Status
+}
+
+// Provider sends agreements for e-signature and reads the callbacks it
+// makes as the envelopes progress.
+type Provider interface {
+	// Name is stored with each agreement the provider sent.
+	Name() string
+
+	// Send creates an envelope for the signer and returns its ID.
+	Send(ctx context.Context, envelope Envelope) (string, error)
+
+	// ParseCallback authenticates the callback made with body and header
+	// and reads the update it carries. It returns ErrUnauthorized when the
+	// callback is not from the provider and ErrInvalidArgument when it
+	// cannot be read.
+	ParseCallback(body []byte, header http.Header) (*StatusUpdate, error)
+}
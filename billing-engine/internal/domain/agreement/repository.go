@@ -0,0 +1,31 @@
+package agreement
+
+import (
+	"context"
+	"time"
+)
+
+type Repository interface {
+	// Create stores a and sets its ID and timestamps. It returns ErrNotFound
+	// for an unknown loan and ErrConflict when the loan already has an open
+	// agreement or provider already has an envelope with the same ID.
+	Create(ctx context.Context, a *Agreement) error
+
+	// GetLatest returns the loan's most recent agreement, or ErrNotFound
+	// when it has none.
+	GetLatest(ctx context.Context, loanID int64) (*Agreement, error)
+
+	// GetByEnvelope returns ErrNotFound when provider sent no envelope with
+	// that ID.
+	GetByEnvelope(ctx context.Context, provider, envelopeID string) (*Agreement, error)
+
+	// UpdateStatus moves a SENT agreement to status at at, stamping SignedAt
+	// when it is COMPLETED. It returns ErrConflict when the agreement is no
+	// longer SENT.
+	UpdateStatus(ctx context.Context, agreementID int64, status Status, at time.Time) error
+
+	// MarkDisbursed records that the loan of a COMPLETED agreement was
+	// disbursed. It returns ErrConflict when the agreement is not COMPLETED
+	// or was disbursed already.
+	MarkDisbursed(ctx context.Context, agreementID int64, disbursedBy string, at time.Time) error
+}
@@ -0,0 +1,56 @@
+package agreement
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) Create(ctx context.Context, a *Agreement) error {
+	return m.Called(ctx, a).Error(0)
+}
+
+func (m *MockRepository) GetLatest(ctx context.Context, loanID int64) (*Agreement, error) {
+	args := m.Called(ctx, loanID)
+	a, _ := args.Get(0).(*Agreement)
+	return a, args.Error(1)
+}
+
+func (m *MockRepository) GetByEnvelope(ctx context.Context, provider, envelopeID string) (*Agreement, error) {
+	args := m.Called(ctx, provider, envelopeID)
+	a, _ := args.Get(0).(*Agreement)
+	return a, args.Error(1)
+}
+
+func (m *MockRepository) UpdateStatus(ctx context.Context, agreementID int64, status Status, at time.Time) error {
+	return m.Called(ctx, agreementID, status, at).Error(0)
+}
+
+func (m *MockRepository) MarkDisbursed(ctx context.Context, agreementID int64, disbursedBy string, at time.Time) error {
+	return m.Called(ctx, agreementID, disbursedBy, at).Error(0)
+}
+
+type MockProvider struct {
+	mock.Mock
+}
+
+func (m *MockProvider) Name() string {
+	return "mock"
+}
+
+func (m *MockProvider) Send(ctx context.Context, envelope Envelope) (string, error) {
+	args := m.Called(ctx, envelope)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockProvider) ParseCallback(body []byte, header http.Header) (*StatusUpdate, error) {
+	args := m.Called(body, header)
+	u, _ := args.Get(0).(*StatusUpdate)
+	return u, args.Error(1)
+}
@@ -0,0 +1,299 @@
+package agreement
+
+import (
+	"billing-engine/internal/domain/contact"
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/domain/document"
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+)
+
+type Service interface {
+	// CreateLoan creates a loan in workflow mode: the loan is placed on hold
+	// and its agreement generated and sent to the customer for signature.
+	// When the loan was created but its agreement could not be sent, the
+	// loan is returned along with the error; creating it again with the
+	// same public ID, or Send, sends the agreement.
+	CreateLoan(ctx context.Context, terms NewLoan, createdBy string) (*loan.Loan, *Agreement, error)
+
+	// Send sends the loan a new agreement, such as after the last one was
+	// declined or voided. A loan whose agreement is still sent or signed
+	// gets that one back instead.
+	Send(ctx context.Context, loanID int64, sentBy string) (*Agreement, error)
+
+	// Get returns the loan's most recent agreement.
+	Get(ctx context.Context, loanID int64) (*Agreement, error)
+
+	// HandleCallback applies a callback of the e-signature provider to the
+	// agreement of its envelope. A repeated callback changes nothing, and
+	// one that contradicts a final status is refused with ErrConflict.
+	HandleCallback(ctx context.Context, body []byte, header http.Header) (*Agreement, error)
+
+	// Disburse records that the loan was paid out and releases the hold it
+	// awaited signature under. It returns ErrConflict unless the loan's
+	// agreement is signed; disbursing a loan again returns its agreement
+	// unchanged.
+	Disburse(ctx context.Context, loanID int64, disbursedBy string) (*Agreement, error)
+}
+
+var _ Service = (*service)(nil)
+
+type service struct {
+	repo      Repository
+	loans     loan.LoanService
+	customers customer.CustomerService
+	contacts  contact.Service
+	documents document.Service
+	provider  Provider
+	clock     clock.Clock
+	logger    *slog.Logger
+}
+
+// NewService wires the agreement service. provider may be nil when no
+// e-signature provider is configured: agreements are read and loans
+// disbursed, but no loan is created in workflow mode and no agreement sent.
+func NewService(repo Repository, loans loan.LoanService, customers customer.CustomerService, contacts contact.Service, documents document.Service, provider Provider, clk clock.Clock, logger *slog.Logger) Service {
+	if repo == nil || loans == nil || customers == nil || contacts == nil || documents == nil {
+		panic("agreement repository, loan, customer, contact and document services cannot be nil")
+	}
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+		logger.Warn("Warning: No logger provided to agreement.NewService, using default stderr handler")
+	}
+	return &service{
+		repo:      repo,
+		loans:     loans,
+		customers: customers,
+		contacts:  contacts,
+		documents: documents,
+		provider:  provider,
+		clock:     clock.OrSystem(clk),
+		logger:    logger.With(slog.String("component", "agreementService")),
+	}
+}
+
+func (s *service) requireProvider() error {
+	if s.provider == nil {
+		return fmt.Errorf("%w: no e-signature provider is configured", apperrors.ErrUnavailable)
+	}
+	return nil
+}
+
+// CreateLoan finds the signer before it creates the loan, so that a
+// customer without an address to sign from gets no loan.
+func (s *service) CreateLoan(ctx context.Context, terms NewLoan, createdBy string) (*loan.Loan, *Agreement, error) {
+	if err := s.requireProvider(); err != nil {
+		return nil, nil, err
+	}
+	cust, err := s.customers.GetCustomer(ctx, terms.CustomerID)
+	if err != nil {
+		if errors.Is(err, apperrors.ErrNotFound) {
+			return nil, nil, fmt.Errorf("%w: customer %d not found", apperrors.ErrValidation, terms.CustomerID)
+		}
+		return nil, nil, fmt.Errorf("failed to get customer %d: %w", terms.CustomerID, err)
+	}
+	signer, err := s.signer(ctx, cust)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	l, err := s.loans.CreateLoan(ctx, terms.CustomerID, terms.Principal, terms.TermWeeks, terms.AnnualInterestRate, terms.StartDate, terms.ExternalRef, terms.PublicID, terms.Product)
+	if err != nil {
+		return nil, nil, err
+	}
+	a, err := s.send(ctx, l, cust, signer, createdBy)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Workflow loan created without its agreement", slog.Int64("loanID", l.ID), slog.Any("error", err))
+		return l, nil, fmt.Errorf("loan %d was created but its agreement was not sent: %w", l.ID, err)
+	}
+	return l, a, nil
+}
+
+func (s *service) Send(ctx context.Context, loanID int64, sentBy string) (*Agreement, error) {
+	if err := s.requireProvider(); err != nil {
+		return nil, err
+	}
+	l, err := s.loans.GetLoan(ctx, loanID, false)
+	if err != nil {
+		return nil, err
+	}
+	cust, err := s.customers.FindCustomerByLoan(ctx, loanID)
+	if err != nil {
+		if errors.Is(err, apperrors.ErrNotFound) {
+			return nil, fmt.Errorf("%w: loan %d has no customer to sign its agreement", apperrors.ErrConflict, loanID)
+		}
+		return nil, fmt.Errorf("failed to find the customer of loan %d: %w", loanID, err)
+	}
+	signer, err := s.signer(ctx, cust)
+	if err != nil {
+		return nil, err
+	}
+	return s.send(ctx, l, cust, signer, sentBy)
+}
+
+// signer is the customer at their primary email address, which has to be
+// verified for the agreement to be sent to it.
+func (s *service) signer(ctx context.Context, cust *customer.Customer) (Signer, error) {
+	contacts, err := s.contacts.ListContacts(ctx, cust.CustomerID)
+	if err != nil {
+		return Signer{}, fmt.Errorf("failed to list the contacts of customer %d: %w", cust.CustomerID, err)
+	}
+	for _, c := range contacts {
+		if c.Type == contact.TypeEmail && c.Primary && c.Verified {
+			return Signer{Name: cust.Name, Email: c.Value}, nil
+		}
+	}
+	return Signer{}, fmt.Errorf("%w: customer %d has no verified primary email to sign the agreement from", apperrors.ErrValidation, cust.CustomerID)
+}
+
+// send returns the loan's open agreement when it has one. Otherwise it
+// places the loan on hold, unless it is on hold already, then stores the
+// agreement and sends it. An envelope that was sent but could not be
+// recorded is logged, to be voided at the provider.
+func (s *service) send(ctx context.Context, l *loan.Loan, cust *customer.Customer, signer Signer, sentBy string) (*Agreement, error) {
+	latest, err := s.repo.GetLatest(ctx, l.ID)
+	switch {
+	case err == nil && latest.Status.open():
+		return latest, nil
+	case err != nil && !errors.Is(err, apperrors.ErrNotFound):
+		return nil, err
+	}
+
+	holdID, err := s.hold(ctx, l, sentBy)
+	if err != nil {
+		return nil, err
+	}
+	externalRef := ""
+	if l.ExternalRef != nil {
+		externalRef = *l.ExternalRef
+	}
+	d, content, err := s.documents.IssueLoanAgreement(ctx, document.LoanAgreement{
+		LoanID: l.ID, PublicID: l.PublicID, ExternalRef: externalRef, CustomerName: cust.Name,
+		Principal: l.PrincipalAmount, AnnualInterestRate: l.InterestRate, TermWeeks: l.TermWeeks,
+		WeeklyInstallment: l.WeeklyPaymentAmount, TotalAmount: l.TotalLoanAmount, FirstDueDate: l.NextDueDate,
+	})
+	if err != nil {
+		return nil, err
+	}
+	envelopeID, err := s.provider.Send(ctx, Envelope{
+		LoanID: l.ID, Reference: l.PublicID.String(), Signer: signer,
+		FileName: d.FileName, ContentType: d.ContentType, Content: content,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to send the agreement of loan %d for signature: %v", apperrors.ErrUnavailable, l.ID, err)
+	}
+
+	a := &Agreement{
+		LoanID: l.ID, DocumentID: &d.ID, HoldID: holdID, Provider: s.provider.Name(), EnvelopeID: envelopeID,
+		Status: StatusSent, SentBy: sentBy, SentAt: s.clock.Now(),
+	}
+	if err := s.repo.Create(ctx, a); err != nil {
+		s.logger.ErrorContext(ctx, "Failed to record sent agreement", slog.Int64("loanID", l.ID), slog.String("envelopeID", envelopeID), slog.Any("error", err))
+		if errors.Is(err, apperrors.ErrConflict) {
+			return s.repo.GetLatest(ctx, l.ID)
+		}
+		return nil, err
+	}
+	s.logger.InfoContext(ctx, "Loan agreement sent for signature", slog.Int64("loanID", l.ID), slog.Int64("agreementID", a.ID), slog.String("envelopeID", envelopeID))
+	return a, nil
+}
+
+// hold returns the hold the loan awaits signature under: the one placed
+// for an earlier agreement, or a new one. A hold placed for another reason
+// is left to whoever placed it, so no hold is returned.
+func (s *service) hold(ctx context.Context, l *loan.Loan, placedBy string) (*int64, error) {
+	if l.Hold != nil {
+		if l.Hold.Reason == HoldReason {
+			return &l.Hold.ID, nil
+		}
+		return nil, nil
+	}
+	hold, err := s.loans.PlaceHold(ctx, l.ID, HoldReason, placedBy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to place loan %d on hold: %w", l.ID, err)
+	}
+	l.Hold = hold
+	return &hold.ID, nil
+}
+
+func (s *service) Get(ctx context.Context, loanID int64) (*Agreement, error) {
+	if _, err := s.loans.GetLoan(ctx, loanID, false); err != nil {
+		return nil, err
+	}
+	return s.repo.GetLatest(ctx, loanID)
+}
+
+// HandleCallback ignores an update back to SENT, which providers send as
+// the envelope is delivered and viewed.
+func (s *service) HandleCallback(ctx context.Context, body []byte, header http.Header) (*Agreement, error) {
+	if err := s.requireProvider(); err != nil {
+		return nil, err
+	}
+	update, err := s.provider.ParseCallback(body, header)
+	if err != nil {
+		return nil, err
+	}
+	a, err := s.repo.GetByEnvelope(ctx, s.provider.Name(), update.EnvelopeID)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case update.Status == a.Status || update.Status == StatusSent:
+		return a, nil
+	case a.Status.Final():
+		return nil, fmt.Errorf("%w: envelope %s is already %s", apperrors.ErrConflict, update.EnvelopeID, a.Status)
+	}
+
+	now := s.clock.Now()
+	if err := s.repo.UpdateStatus(ctx, a.ID, update.Status, now); err != nil {
+		return nil, err
+	}
+	a.Status, a.UpdatedAt = update.Status, now
+	if update.Status == StatusCompleted {
+		a.SignedAt = &now
+	}
+	s.logger.InfoContext(ctx, "Loan agreement status changed", slog.Int64("loanID", a.LoanID), slog.Int64("agreementID", a.ID), slog.String("status", string(a.Status)))
+	return a, nil
+}
+
+// Disburse releases the hold before it records the disbursement, so that a
+// retry after a failure in between finds the hold gone and records it.
+func (s *service) Disburse(ctx context.Context, loanID int64, disbursedBy string) (*Agreement, error) {
+	l, err := s.loans.GetLoan(ctx, loanID, false)
+	if err != nil {
+		return nil, err
+	}
+	a, err := s.repo.GetLatest(ctx, loanID)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return nil, fmt.Errorf("%w: loan %d was not created in workflow mode", apperrors.ErrConflict, loanID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if a.DisbursedAt != nil {
+		return a, nil
+	}
+	if a.Status != StatusCompleted {
+		return nil, fmt.Errorf("%w: the agreement of loan %d is %s; the loan is disbursed once it is signed", apperrors.ErrConflict, loanID, a.Status)
+	}
+
+	if a.HoldID != nil && l.Hold != nil && l.Hold.ID == *a.HoldID {
+		if _, err := s.loans.ReleaseHold(ctx, loanID, disbursedBy); err != nil && !errors.Is(err, apperrors.ErrNotFound) {
+			return nil, fmt.Errorf("failed to release the hold of loan %d: %w", loanID, err)
+		}
+	}
+	now := s.clock.Now()
+	if err := s.repo.MarkDisbursed(ctx, a.ID, disbursedBy, now); err != nil {
+		return nil, err
+	}
+	a.DisbursedBy, a.DisbursedAt, a.UpdatedAt = disbursedBy, &now, now
+	s.logger.InfoContext(ctx, "Loan disbursed", slog.Int64("loanID", loanID), slog.Int64("agreementID", a.ID), slog.String("disbursedBy", disbursedBy))
+	return a, nil
+}
@@ -0,0 +1,389 @@
+package agreement
+
+import (
+	"billing-engine/internal/domain/contact"
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/domain/document"
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+var testNow = time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC)
+
+type fakeLoanService struct {
+	loan.LoanService
+	loans    map[int64]*loan.Loan
+	created  int
+	released int
+	holdErr  error
+}
+
+func (f *fakeLoanService) CreateLoan(_ context.Context, customerID int64, principal loan.Money, termWeeks int, rate loan.Money, startDate time.Time, _ string, publicID uuid.UUID, _ string) (*loan.Loan, error) {
+	f.created++
+	l := &loan.Loan{ID: 42, PublicID: publicID, PrincipalAmount: principal, InterestRate: rate, TermWeeks: termWeeks, StartDate: startDate}
+	f.loans[l.ID] = l
+	return l, nil
+}
+
+func (f *fakeLoanService) GetLoan(_ context.Context, loanID int64, _ bool) (*loan.Loan, error) {
+	l, ok := f.loans[loanID]
+	if !ok {
+		return nil, fmt.Errorf("%w: loan with ID %d not found", apperrors.ErrNotFound, loanID)
+	}
+	copied := *l
+	return &copied, nil
+}
+
+func (f *fakeLoanService) PlaceHold(_ context.Context, loanID int64, reason, placedBy string) (*loan.Hold, error) {
+	if f.holdErr != nil {
+		return nil, f.holdErr
+	}
+	hold := &loan.Hold{ID: 5, LoanID: loanID, Reason: reason, PlacedBy: &placedBy, PlacedAt: testNow}
+	f.loans[loanID].Hold = hold
+	return hold, nil
+}
+
+func (f *fakeLoanService) ReleaseHold(_ context.Context, loanID int64, _ string) (*loan.Hold, error) {
+	f.released++
+	hold := f.loans[loanID].Hold
+	f.loans[loanID].Hold = nil
+	return hold, nil
+}
+
+type fakeCustomerService struct {
+	customer.CustomerService
+}
+
+func (fakeCustomerService) GetCustomer(_ context.Context, customerID int64) (*customer.Customer, error) {
+	if customerID != 9 {
+		return nil, fmt.Errorf("%w: customer %d", apperrors.ErrNotFound, customerID)
+	}
+	return &customer.Customer{CustomerID: 9, Name: "Budi", Active: true}, nil
+}
+
+func (f fakeCustomerService) FindCustomerByLoan(ctx context.Context, _ int64) (*customer.Customer, error) {
+	return f.GetCustomer(ctx, 9)
+}
+
+type fakeContactService struct {
+	contact.Service
+	contacts []contact.Contact
+}
+
+func (f *fakeContactService) ListContacts(context.Context, int64) ([]contact.Contact, error) {
+	return f.contacts, nil
+}
+
+type fakeDocumentService struct {
+	document.Service
+	issued []document.LoanAgreement
+}
+
+func (f *fakeDocumentService) IssueLoanAgreement(_ context.Context, a document.LoanAgreement) (*document.Document, []byte, error) {
+	f.issued = append(f.issued, a)
+	return &document.Document{ID: 7, FileName: "loan-agreement-42.pdf", ContentType: document.ContentTypePDF}, []byte("%PDF-1.4"), nil
+}
+
+var verifiedEmail = contact.Contact{ID: 1, CustomerID: 9, Type: contact.TypeEmail, Value: "budi@example.com", Verified: true, Primary: true}
+
+type testDeps struct {
+	repo      *MockRepository
+	provider  *MockProvider
+	loans     *fakeLoanService
+	contacts  *fakeContactService
+	documents *fakeDocumentService
+}
+
+func newTestService(provider bool) (Service, *testDeps) {
+	deps := &testDeps{
+		repo:      new(MockRepository),
+		provider:  new(MockProvider),
+		loans:     &fakeLoanService{loans: map[int64]*loan.Loan{}},
+		contacts:  &fakeContactService{contacts: []contact.Contact{verifiedEmail}},
+		documents: &fakeDocumentService{},
+	}
+	var p Provider
+	if provider {
+		p = deps.provider
+	}
+	return NewService(deps.repo, deps.loans, fakeCustomerService{}, deps.contacts, deps.documents, p, clock.NewFake(testNow), testLogger), deps
+}
+
+func TestParseStatus(t *testing.T) {
+	status, err := ParseStatus(" completed ")
+	require.NoError(t, err)
+	assert.Equal(t, StatusCompleted, status)
+	assert.True(t, status.Final())
+	assert.False(t, StatusSent.Final())
+
+	_, err = ParseStatus("SIGNED")
+	assert.Error(t, err)
+}
+
+func TestServiceCreateLoan(t *testing.T) {
+	ctx := context.Background()
+	terms := NewLoan{CustomerID: 9, Principal: 5_000_000, TermWeeks: 50, AnnualInterestRate: 0.1, PublicID: uuid.New()}
+
+	t.Run("creates the loan on hold and sends its agreement", func(t *testing.T) {
+		svc, deps := newTestService(true)
+		deps.repo.On("GetLatest", ctx, int64(42)).Return(nil, apperrors.ErrNotFound)
+		deps.provider.On("Send", ctx, mock.MatchedBy(func(e Envelope) bool {
+			return e.LoanID == 42 && e.Reference == terms.PublicID.String() && e.Signer == Signer{Name: "Budi", Email: "budi@example.com"} &&
+				e.FileName == "loan-agreement-42.pdf" && string(e.Content) == "%PDF-1.4"
+		})).Return("env-1", nil)
+		deps.repo.On("Create", ctx, mock.MatchedBy(func(a *Agreement) bool {
+			a.ID = 3
+			return a.LoanID == 42 && *a.DocumentID == 7 && *a.HoldID == 5 && a.Provider == "mock" && a.EnvelopeID == "env-1" &&
+				a.Status == StatusSent && a.SentBy == "ops@example.com" && a.SentAt.Equal(testNow)
+		})).Return(nil)
+
+		l, a, err := svc.CreateLoan(ctx, terms, "ops@example.com")
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(42), l.ID)
+		require.NotNil(t, l.Hold)
+		assert.Equal(t, HoldReason, l.Hold.Reason)
+		assert.Equal(t, int64(3), a.ID)
+		require.Len(t, deps.documents.issued, 1)
+		assert.Equal(t, "Budi", deps.documents.issued[0].CustomerName)
+		deps.repo.AssertExpectations(t)
+	})
+
+	t.Run("returns the open agreement of a loan created again", func(t *testing.T) {
+		svc, deps := newTestService(true)
+		sent := &Agreement{ID: 3, LoanID: 42, Status: StatusSent}
+		deps.repo.On("GetLatest", ctx, int64(42)).Return(sent, nil)
+
+		_, a, err := svc.CreateLoan(ctx, terms, "ops@example.com")
+
+		require.NoError(t, err)
+		assert.Same(t, sent, a)
+		deps.provider.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+	})
+
+	t.Run("refuses a customer without a verified email before creating the loan", func(t *testing.T) {
+		svc, deps := newTestService(true)
+		unverified := verifiedEmail
+		unverified.Verified, unverified.Primary = false, false
+		deps.contacts.contacts = []contact.Contact{unverified}
+
+		_, _, err := svc.CreateLoan(ctx, terms, "")
+
+		assert.ErrorIs(t, err, apperrors.ErrValidation)
+		assert.Zero(t, deps.loans.created)
+	})
+
+	t.Run("returns the loan when the agreement cannot be sent", func(t *testing.T) {
+		svc, deps := newTestService(true)
+		deps.repo.On("GetLatest", ctx, int64(42)).Return(nil, apperrors.ErrNotFound)
+		deps.provider.On("Send", ctx, mock.Anything).Return("", errors.New("connection refused"))
+
+		l, a, err := svc.CreateLoan(ctx, terms, "")
+
+		assert.ErrorIs(t, err, apperrors.ErrUnavailable)
+		require.NotNil(t, l)
+		assert.Nil(t, a)
+		assert.NotNil(t, deps.loans.loans[42].Hold, "the loan stays on hold until an agreement is sent")
+	})
+
+	t.Run("needs a provider", func(t *testing.T) {
+		svc, deps := newTestService(false)
+
+		_, _, err := svc.CreateLoan(ctx, terms, "")
+
+		assert.ErrorIs(t, err, apperrors.ErrUnavailable)
+		assert.Zero(t, deps.loans.created)
+	})
+}
+
+func TestServiceSend(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("sends a new agreement under the hold of the declined one", func(t *testing.T) {
+		svc, deps := newTestService(true)
+		deps.loans.loans[42] = &loan.Loan{ID: 42, Hold: &loan.Hold{ID: 5, Reason: HoldReason}}
+		deps.repo.On("GetLatest", ctx, int64(42)).Return(&Agreement{ID: 3, LoanID: 42, Status: StatusDeclined}, nil)
+		deps.provider.On("Send", ctx, mock.Anything).Return("env-2", nil)
+		deps.repo.On("Create", ctx, mock.MatchedBy(func(a *Agreement) bool { return *a.HoldID == 5 && a.EnvelopeID == "env-2" })).Return(nil)
+
+		a, err := svc.Send(ctx, 42, "")
+
+		require.NoError(t, err)
+		assert.Equal(t, "env-2", a.EnvelopeID)
+		deps.repo.AssertExpectations(t)
+	})
+
+	t.Run("leaves a hold placed for another reason alone", func(t *testing.T) {
+		svc, deps := newTestService(true)
+		deps.loans.loans[42] = &loan.Loan{ID: 42, Hold: &loan.Hold{ID: 6, Reason: "Dispute"}}
+		deps.repo.On("GetLatest", ctx, int64(42)).Return(nil, apperrors.ErrNotFound)
+		deps.provider.On("Send", ctx, mock.Anything).Return("env-2", nil)
+		deps.repo.On("Create", ctx, mock.MatchedBy(func(a *Agreement) bool { return a.HoldID == nil })).Return(nil)
+
+		_, err := svc.Send(ctx, 42, "")
+
+		require.NoError(t, err)
+		deps.repo.AssertExpectations(t)
+	})
+
+	t.Run("does not send when the loan cannot be placed on hold", func(t *testing.T) {
+		svc, deps := newTestService(true)
+		deps.loans.loans[42] = &loan.Loan{ID: 42}
+		deps.loans.holdErr = fmt.Errorf("%w: loan 42 is already on hold", apperrors.ErrConflict)
+		deps.repo.On("GetLatest", ctx, int64(42)).Return(nil, apperrors.ErrNotFound)
+
+		_, err := svc.Send(ctx, 42, "")
+
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+		assert.Empty(t, deps.documents.issued)
+	})
+}
+
+func TestServiceHandleCallback(t *testing.T) {
+	ctx := context.Background()
+	body := []byte(`{"envelopeId":"env-1","status":"completed"}`)
+	header := http.Header{"X-Signature": {"sha256=abc"}}
+
+	t.Run("records the signature", func(t *testing.T) {
+		svc, deps := newTestService(true)
+		deps.provider.On("ParseCallback", body, header).Return(&StatusUpdate{EnvelopeID: "env-1", Status: StatusCompleted}, nil)
+		deps.repo.On("GetByEnvelope", ctx, "mock", "env-1").Return(&Agreement{ID: 3, LoanID: 42, Status: StatusSent}, nil)
+		deps.repo.On("UpdateStatus", ctx, int64(3), StatusCompleted, testNow).Return(nil)
+
+		a, err := svc.HandleCallback(ctx, body, header)
+
+		require.NoError(t, err)
+		assert.Equal(t, StatusCompleted, a.Status)
+		assert.Equal(t, &testNow, a.SignedAt)
+	})
+
+	t.Run("changes nothing on a repeated or intermediate callback", func(t *testing.T) {
+		for name, update := range map[string]StatusUpdate{
+			"repeated":  {EnvelopeID: "env-1", Status: StatusCompleted},
+			"delivered": {EnvelopeID: "env-1", Status: StatusSent},
+		} {
+			t.Run(name, func(t *testing.T) {
+				svc, deps := newTestService(true)
+				deps.provider.On("ParseCallback", body, header).Return(&update, nil)
+				deps.repo.On("GetByEnvelope", ctx, "mock", "env-1").Return(&Agreement{ID: 3, Status: StatusCompleted}, nil)
+
+				a, err := svc.HandleCallback(ctx, body, header)
+
+				require.NoError(t, err)
+				assert.Equal(t, StatusCompleted, a.Status)
+				deps.repo.AssertNotCalled(t, "UpdateStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			})
+		}
+	})
+
+	t.Run("refuses to change a final status", func(t *testing.T) {
+		svc, deps := newTestService(true)
+		deps.provider.On("ParseCallback", body, header).Return(&StatusUpdate{EnvelopeID: "env-1", Status: StatusCompleted}, nil)
+		deps.repo.On("GetByEnvelope", ctx, "mock", "env-1").Return(&Agreement{ID: 3, Status: StatusVoided}, nil)
+
+		_, err := svc.HandleCallback(ctx, body, header)
+
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+	})
+
+	t.Run("refuses a callback the provider does not vouch for", func(t *testing.T) {
+		svc, deps := newTestService(true)
+		deps.provider.On("ParseCallback", body, header).Return(nil, fmt.Errorf("%w: bad signature", apperrors.ErrUnauthorized))
+
+		_, err := svc.HandleCallback(ctx, body, header)
+
+		assert.ErrorIs(t, err, apperrors.ErrUnauthorized)
+		deps.repo.AssertNotCalled(t, "GetByEnvelope", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestServiceDisburse(t *testing.T) {
+	ctx := context.Background()
+	holdID := int64(5)
+
+	t.Run("releases the hold and records the disbursement of a signed loan", func(t *testing.T) {
+		svc, deps := newTestService(false)
+		deps.loans.loans[42] = &loan.Loan{ID: 42, Hold: &loan.Hold{ID: holdID, Reason: HoldReason}}
+		deps.repo.On("GetLatest", ctx, int64(42)).Return(&Agreement{ID: 3, LoanID: 42, HoldID: &holdID, Status: StatusCompleted}, nil)
+		deps.repo.On("MarkDisbursed", ctx, int64(3), "ops@example.com", testNow).Return(nil)
+
+		a, err := svc.Disburse(ctx, 42, "ops@example.com")
+
+		require.NoError(t, err)
+		assert.Equal(t, &testNow, a.DisbursedAt)
+		assert.Equal(t, 1, deps.loans.released)
+		assert.Nil(t, deps.loans.loans[42].Hold)
+	})
+
+	t.Run("keeps a hold placed for another reason", func(t *testing.T) {
+		svc, deps := newTestService(false)
+		deps.loans.loans[42] = &loan.Loan{ID: 42, Hold: &loan.Hold{ID: 6, Reason: "Dispute"}}
+		deps.repo.On("GetLatest", ctx, int64(42)).Return(&Agreement{ID: 3, LoanID: 42, HoldID: &holdID, Status: StatusCompleted}, nil)
+		deps.repo.On("MarkDisbursed", ctx, int64(3), "", testNow).Return(nil)
+
+		_, err := svc.Disburse(ctx, 42, "")
+
+		require.NoError(t, err)
+		assert.Zero(t, deps.loans.released)
+	})
+
+	t.Run("refuses a loan whose agreement is not signed", func(t *testing.T) {
+		for _, status := range []Status{StatusSent, StatusDeclined, StatusVoided} {
+			svc, deps := newTestService(false)
+			deps.loans.loans[42] = &loan.Loan{ID: 42, Hold: &loan.Hold{ID: holdID, Reason: HoldReason}}
+			deps.repo.On("GetLatest", ctx, int64(42)).Return(&Agreement{ID: 3, LoanID: 42, HoldID: &holdID, Status: status}, nil)
+
+			_, err := svc.Disburse(ctx, 42, "")
+
+			assert.ErrorIs(t, err, apperrors.ErrConflict, status)
+			assert.Zero(t, deps.loans.released)
+		}
+	})
+
+	t.Run("refuses a loan not created in workflow mode", func(t *testing.T) {
+		svc, deps := newTestService(false)
+		deps.loans.loans[42] = &loan.Loan{ID: 42}
+		deps.repo.On("GetLatest", ctx, int64(42)).Return(nil, fmt.Errorf("%w: no agreement", apperrors.ErrNotFound))
+
+		_, err := svc.Disburse(ctx, 42, "")
+
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+	})
+
+	t.Run("returns a disbursed agreement unchanged", func(t *testing.T) {
+		svc, deps := newTestService(false)
+		deps.loans.loans[42] = &loan.Loan{ID: 42}
+		disbursed := &Agreement{ID: 3, LoanID: 42, Status: StatusCompleted, DisbursedAt: &testNow}
+		deps.repo.On("GetLatest", ctx, int64(42)).Return(disbursed, nil)
+
+		a, err := svc.Disburse(ctx, 42, "")
+
+		require.NoError(t, err)
+		assert.Same(t, disbursed, a)
+		deps.repo.AssertNotCalled(t, "MarkDisbursed", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("returns ErrNotFound for an unknown loan", func(t *testing.T) {
+		svc, _ := newTestService(false)
+
+		_, err := svc.Disburse(ctx, 99, "")
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+}
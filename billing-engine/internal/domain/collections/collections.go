@@ -0,0 +1,204 @@
+package collections
+
+import (
+	"billing-engine/internal/domain/loan"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type AssignmentStatus string
+
+const (
+	AssignmentOpen     AssignmentStatus = "OPEN"
+	AssignmentResolved AssignmentStatus = "RESOLVED"
+)
+
+// Resolution says why an assignment left the queue.
+type Resolution string
+
+const (
+	// ResolutionCured means the loan has nothing past due any more.
+	ResolutionCured Resolution = "CURED"
+	// ResolutionPaidOff means the loan was repaid in full.
+	ResolutionPaidOff Resolution = "PAID_OFF"
+)
+
+type ActionType string
+
+const (
+	ActionCall         ActionType = "CALL"
+	ActionSMS          ActionType = "SMS"
+	ActionVisit        ActionType = "VISIT"
+	ActionPromiseToPay ActionType = "PROMISE_TO_PAY"
+	ActionNote         ActionType = "NOTE"
+	// ActionReassigned is recorded by Reassign and cannot be recorded
+	// directly.
+	ActionReassigned ActionType = "REASSIGNED"
+)
+
+// ActionTypes lists the types a collector can record.
+var ActionTypes = []ActionType{ActionCall, ActionSMS, ActionVisit, ActionPromiseToPay, ActionNote}
+
+const (
+	MaxRuleNameLength  = 100
+	MaxCollectorLength = 64
+	MaxRegionLength    = 100
+	MaxActionNoteBytes = 4 << 10
+)
+
+// Rule routes past-due loans to a collector. Empty criteria match every
+// loan, and the first active rule by priority that matches wins.
+type Rule struct {
+	ID        int64
+	Name      string
+	Collector string
+	// Bucket is a days-past-due bucket of the portfolio report, such as
+	// "1-30" or "90+".
+	Bucket string
+	// Region is matched case-insensitively anywhere in the customer's
+	// address, which is the only location a customer has.
+	Region string
+	// MinOutstanding and MaxOutstanding bound the outstanding balance,
+	// inclusive.
+	MinOutstanding *loan.Money
+	MaxOutstanding *loan.Money
+	// Priority orders the rules, lowest first. Rules of equal priority are
+	// tried in the order they were created.
+	Priority  int
+	Active    bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Candidate is a past-due loan that is not in anybody's queue.
+type Candidate struct {
+	LoanID      int64
+	CustomerID  int64
+	Address     string
+	DaysPastDue int
+	Outstanding loan.Money
+}
+
+// Assignment puts a loan in the queue of a collector until it is cured or
+// paid off.
+type Assignment struct {
+	ID         int64
+	LoanID     int64
+	CustomerID int64
+	Collector  string
+	// RuleID is the rule that assigned the loan. It is nil once the rule is
+	// deleted.
+	RuleID     *int64
+	Status     AssignmentStatus
+	Resolution Resolution
+	AssignedAt time.Time
+	ResolvedAt *time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+
+	// The fields below describe the loan as it is now and are filled in
+	// when open assignments are listed.
+	CustomerName string
+	LoanStatus   loan.LoanStatus
+	DaysPastDue  int
+	Outstanding  loan.Money
+	LastActionAt *time.Time
+}
+
+// Action is something a collector did about an assigned loan.
+type Action struct {
+	ID           int64
+	AssignmentID int64
+	LoanID       int64
+	Collector    string
+	Type         ActionType
+	Note         string
+	// PromisedAmount and PromisedDate record a promise to pay and are
+	// required for ActionPromiseToPay only.
+	PromisedAmount *loan.Money
+	PromisedDate   *time.Time
+	CreatedAt      time.Time
+}
+
+// RunReport summarises one assignment run.
+type RunReport struct {
+	Resolved  int
+	Assigned  int
+	Unmatched int
+	Open      int
+}
+
+// Matches reports whether the rule applies to c.
+func (r *Rule) Matches(c Candidate) bool {
+	if !r.Active {
+		return false
+	}
+	if r.Bucket != "" && loan.DPDBucket(c.DaysPastDue) != r.Bucket {
+		return false
+	}
+	if r.Region != "" && !strings.Contains(strings.ToLower(c.Address), strings.ToLower(r.Region)) {
+		return false
+	}
+	if r.MinOutstanding != nil && c.Outstanding < *r.MinOutstanding {
+		return false
+	}
+	if r.MaxOutstanding != nil && c.Outstanding > *r.MaxOutstanding {
+		return false
+	}
+	return true
+}
+
+func (r *Rule) normalize() {
+	r.Name = strings.TrimSpace(r.Name)
+	r.Collector = strings.TrimSpace(r.Collector)
+	r.Bucket = strings.TrimSpace(r.Bucket)
+	r.Region = strings.TrimSpace(r.Region)
+}
+
+func (r *Rule) validate() error {
+	switch {
+	case r.Name == "":
+		return fmt.Errorf("rule name cannot be empty")
+	case utf8.RuneCountInString(r.Name) > MaxRuleNameLength:
+		return fmt.Errorf("rule name cannot exceed %d characters", MaxRuleNameLength)
+	case r.Collector == "":
+		return fmt.Errorf("collector cannot be empty")
+	case len(r.Collector) > MaxCollectorLength:
+		return fmt.Errorf("collector cannot exceed %d characters", MaxCollectorLength)
+	case r.Bucket != "" && (r.Bucket == loan.DPDBucket(0) || !slices.Contains(loan.DPDBuckets(), r.Bucket)):
+		return fmt.Errorf("unknown bucket %q, use 1-30, 31-60, 61-90 or 90+", r.Bucket)
+	case utf8.RuneCountInString(r.Region) > MaxRegionLength:
+		return fmt.Errorf("region cannot exceed %d characters", MaxRegionLength)
+	case r.MinOutstanding != nil && *r.MinOutstanding < 0:
+		return fmt.Errorf("minimum outstanding cannot be negative")
+	case r.MinOutstanding != nil && r.MaxOutstanding != nil && *r.MaxOutstanding < *r.MinOutstanding:
+		return fmt.Errorf("maximum outstanding cannot be below the minimum")
+	}
+	return nil
+}
+
+func (a *Action) normalize() {
+	a.Type = ActionType(strings.ToUpper(strings.TrimSpace(string(a.Type))))
+	a.Note = strings.TrimSpace(a.Note)
+}
+
+func (a *Action) validate() error {
+	switch {
+	case !slices.Contains(ActionTypes, a.Type):
+		return fmt.Errorf("unknown action type %q, use CALL, SMS, VISIT, PROMISE_TO_PAY or NOTE", a.Type)
+	case len(a.Note) > MaxActionNoteBytes:
+		return fmt.Errorf("note cannot exceed %d bytes", MaxActionNoteBytes)
+	case a.Type == ActionNote && a.Note == "":
+		return fmt.Errorf("a NOTE action needs a note")
+	case a.Type == ActionPromiseToPay && (a.PromisedAmount == nil || a.PromisedDate == nil):
+		return fmt.Errorf("a promise to pay needs the promised amount and date")
+	case a.Type != ActionPromiseToPay && (a.PromisedAmount != nil || a.PromisedDate != nil):
+		return fmt.Errorf("only a promise to pay takes a promised amount and date")
+	case a.PromisedAmount != nil && *a.PromisedAmount <= 0:
+		return fmt.Errorf("promised amount must be positive")
+	}
+	return nil
+}
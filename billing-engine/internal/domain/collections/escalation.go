@@ -0,0 +1,139 @@
+package collections
+
+import (
+	"billing-engine/internal/domain/loan"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ReminderAction is what an escalation step does about a past-due loan.
+// SMS and EMAIL remind the customer; CALL and LETTER are tasks for the
+// collections team.
+type ReminderAction string
+
+const (
+	ReminderSMS    ReminderAction = "SMS"
+	ReminderEmail  ReminderAction = "EMAIL"
+	ReminderCall   ReminderAction = "CALL"
+	ReminderLetter ReminderAction = "LETTER"
+)
+
+// ReminderActions lists the actions an escalation step can take.
+var ReminderActions = []ReminderAction{ReminderSMS, ReminderEmail, ReminderCall, ReminderLetter}
+
+const (
+	MaxEscalationSteps       = 20
+	MaxEscalationDaysPastDue = 3650
+)
+
+// EscalationStep is a rung of the reminder ladder. A loan reaches it once it
+// is DaysPastDue days past due.
+type EscalationStep struct {
+	DaysPastDue int
+	Action      ReminderAction
+	CreatedAt   time.Time
+}
+
+// Notifies reports whether the step sends the customer a message rather
+// than giving the collections team a task.
+func (s EscalationStep) Notifies() bool {
+	return s.Action == ReminderSMS || s.Action == ReminderEmail
+}
+
+// Channel is the notify-service channel of a step that notifies.
+func (s EscalationStep) Channel() string {
+	return strings.ToLower(string(s.Action))
+}
+
+// Escalation is a loan as the escalation run sees it: past due, or back to
+// current after it reached a step.
+type Escalation struct {
+	LoanID     int64
+	CustomerID int64
+	// TenantID is the tenant of the loan, whose reminder window applies.
+	TenantID    string
+	LoanStatus  loan.LoanStatus
+	DaysPastDue int
+	// Collector holds the loan's open assignment and is empty while it has
+	// none.
+	Collector string
+	// LastStep is the days past due of the step the loan last reached, nil
+	// while it has reached none.
+	LastStep *int
+}
+
+// EscalationReport summarises one escalation run.
+type EscalationReport struct {
+	Reminders int
+	Tasks     int
+	// Reset counts the loans that left the ladder because nothing is past
+	// due any more.
+	Reset int
+	// Stale counts the SMS and email steps raised without a reminder,
+	// because the loan reached them more than the reminder window ago.
+	Stale int
+}
+
+// ReminderWindowSource gives the reminder window of the tenant ctx is
+// scoped to: an SMS or email step is only sent to a loan that reached it
+// fewer days ago. Zero sends it however late. A ReminderWindowDays is its
+// own source; tenant settings can supply one that changes at run time.
+type ReminderWindowSource interface {
+	ReminderWindow(ctx context.Context) (int, error)
+}
+
+// ReminderWindowDays is a reminder window that is the same for every
+// tenant.
+type ReminderWindowDays int
+
+// ReminderWindow returns d itself.
+func (d ReminderWindowDays) ReminderWindow(context.Context) (int, error) {
+	return int(d), nil
+}
+
+// stale reports whether a loan e.DaysPastDue days past due reached step
+// window or more days ago, too long for its reminder to be sent.
+func stale(step EscalationStep, e Escalation, window int) bool {
+	return step.Notifies() && window > 0 && e.DaysPastDue-step.DaysPastDue >= window
+}
+
+// normalizeLadder validates steps and sorts them by days past due. Two
+// steps cannot share a day, because only one of them would ever fire.
+func normalizeLadder(steps []EscalationStep) ([]EscalationStep, error) {
+	if len(steps) > MaxEscalationSteps {
+		return nil, fmt.Errorf("the ladder cannot have more than %d steps", MaxEscalationSteps)
+	}
+	ladder := make([]EscalationStep, len(steps))
+	for i, step := range steps {
+		step.Action = ReminderAction(strings.ToUpper(strings.TrimSpace(string(step.Action))))
+		switch {
+		case !slices.Contains(ReminderActions, step.Action):
+			return nil, fmt.Errorf("unknown reminder action %q, use SMS, EMAIL, CALL or LETTER", step.Action)
+		case step.DaysPastDue < 1 || step.DaysPastDue > MaxEscalationDaysPastDue:
+			return nil, fmt.Errorf("days past due of a step must be between 1 and %d", MaxEscalationDaysPastDue)
+		}
+		ladder[i] = step
+	}
+	slices.SortFunc(ladder, func(a, b EscalationStep) int { return a.DaysPastDue - b.DaysPastDue })
+	for i := 1; i < len(ladder); i++ {
+		if ladder[i].DaysPastDue == ladder[i-1].DaysPastDue {
+			return nil, fmt.Errorf("more than one step at %d days past due", ladder[i].DaysPastDue)
+		}
+	}
+	return ladder, nil
+}
+
+// stepFor returns the highest step of the sorted ladder that a loan
+// daysPastDue days past due has reached. Steps it went past between two runs
+// are skipped rather than sent together.
+func stepFor(ladder []EscalationStep, daysPastDue int) (EscalationStep, bool) {
+	for i := len(ladder) - 1; i >= 0; i-- {
+		if ladder[i].DaysPastDue <= daysPastDue {
+			return ladder[i], true
+		}
+	}
+	return EscalationStep{}, false
+}
@@ -0,0 +1,242 @@
+package collections
+
+import (
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/event"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"billing-engine/internal/pkg/scope"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// capturePublisher keeps the batches it is handed.
+type capturePublisher struct {
+	messages []event.Message
+}
+
+func (p *capturePublisher) PublishCustomerDelinquencyChanged(context.Context, event.CustomerDelinquencyChangedEvent) error {
+	return nil
+}
+
+func (p *capturePublisher) PublishCustomerCreated(context.Context, event.CustomerCreatedEvent) error {
+	return nil
+}
+
+func (p *capturePublisher) PublishCustomerUpdated(context.Context, event.CustomerUpdatedEvent) error {
+	return nil
+}
+
+func (p *capturePublisher) PublishBatch(_ context.Context, messages []event.Message) error {
+	p.messages = append(p.messages, messages...)
+	return nil
+}
+
+var defaultLadder = []EscalationStep{
+	{DaysPastDue: 1, Action: ReminderSMS},
+	{DaysPastDue: 7, Action: ReminderEmail},
+	{DaysPastDue: 14, Action: ReminderCall},
+	{DaysPastDue: 30, Action: ReminderLetter},
+}
+
+// tenantWindows is a reminder window per tenant; a missing one is no limit.
+type tenantWindows map[string]int
+
+func (w tenantWindows) ReminderWindow(ctx context.Context) (int, error) {
+	return w[scope.TenantOrDefault(ctx)], nil
+}
+
+func step(n int) *int {
+	return &n
+}
+
+func TestNormalizeLadder(t *testing.T) {
+	ladder, err := normalizeLadder([]EscalationStep{
+		{DaysPastDue: 30, Action: "letter"},
+		{DaysPastDue: 1, Action: " sms "},
+		{DaysPastDue: 14, Action: ReminderCall},
+		{DaysPastDue: 7, Action: ReminderEmail},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, defaultLadder, ladder)
+
+	empty, err := normalizeLadder(nil)
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+
+	for name, steps := range map[string][]EscalationStep{
+		"unknown action": {{DaysPastDue: 3, Action: "FAX"}},
+		"current loans":  {{DaysPastDue: 0, Action: ReminderSMS}},
+		"same day twice": {{DaysPastDue: 7, Action: ReminderSMS}, {DaysPastDue: 7, Action: ReminderEmail}},
+		"too late":       {{DaysPastDue: MaxEscalationDaysPastDue + 1, Action: ReminderLetter}},
+		"too many":       make([]EscalationStep, MaxEscalationSteps+1),
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := normalizeLadder(steps)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestStepFor(t *testing.T) {
+	tests := []struct {
+		daysPastDue int
+		want        int
+		ok          bool
+	}{
+		{daysPastDue: 0},
+		{daysPastDue: 1, want: 1, ok: true},
+		{daysPastDue: 6, want: 1, ok: true},
+		{daysPastDue: 7, want: 7, ok: true},
+		{daysPastDue: 20, want: 14, ok: true},
+		{daysPastDue: 400, want: 30, ok: true},
+	}
+	for _, tt := range tests {
+		got, ok := stepFor(defaultLadder, tt.daysPastDue)
+		assert.Equal(t, tt.ok, ok, "days past due %d", tt.daysPastDue)
+		assert.Equal(t, tt.want, got.DaysPastDue, "days past due %d", tt.daysPastDue)
+	}
+}
+
+func TestServiceReplaceEscalationSteps(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("stores the sorted ladder", func(t *testing.T) {
+		repo := new(MockRepository)
+		repo.On("ReplaceEscalationSteps", ctx, defaultLadder).Return(nil)
+
+		ladder, err := newTestService(repo).ReplaceEscalationSteps(ctx, []EscalationStep{
+			{DaysPastDue: 14, Action: "call"}, {DaysPastDue: 1, Action: "sms"}, {DaysPastDue: 30, Action: "letter"}, {DaysPastDue: 7, Action: "email"},
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, defaultLadder, ladder)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("rejects an invalid ladder", func(t *testing.T) {
+		repo := new(MockRepository)
+		_, err := newTestService(repo).ReplaceEscalationSteps(ctx, []EscalationStep{{DaysPastDue: 3, Action: "VISIT"}})
+		assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+		repo.AssertNotCalled(t, "ReplaceEscalationSteps", mock.Anything, mock.Anything)
+	})
+}
+
+func TestServiceEscalate(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("raises the step each loan reached", func(t *testing.T) {
+		repo := new(MockRepository)
+		repo.On("ListEscalationSteps", ctx).Return(defaultLadder, nil)
+		repo.On("ListEscalations", ctx).Return([]Escalation{
+			// First day late: SMS.
+			{LoanID: 1, CustomerID: 11, LoanStatus: loan.StatusActive, DaysPastDue: 1},
+			// Still on the SMS step: nothing new.
+			{LoanID: 2, CustomerID: 12, LoanStatus: loan.StatusActive, DaysPastDue: 5, LastStep: step(1)},
+			// Went past email and call since the last run: only the call.
+			{LoanID: 3, CustomerID: 13, LoanStatus: loan.StatusDelinquent, DaysPastDue: 16, Collector: "alice", LastStep: step(1)},
+			// Caught up: starts over next time.
+			{LoanID: 4, CustomerID: 14, LoanStatus: loan.StatusActive, DaysPastDue: 0, LastStep: step(7)},
+			{LoanID: 5, CustomerID: 15, LoanStatus: loan.StatusPaidOff, DaysPastDue: 3, LastStep: step(1)},
+			{LoanID: 6, CustomerID: 16, LoanStatus: loan.StatusDelinquent, DaysPastDue: 31, LastStep: step(14)},
+		}, nil)
+		repo.On("SetEscalation", ctx, int64(1), 1, testNow).Return(nil)
+		repo.On("SetEscalation", ctx, int64(3), 14, testNow).Return(nil)
+		repo.On("SetEscalation", ctx, int64(6), 30, testNow).Return(nil)
+		repo.On("ClearEscalation", ctx, int64(4)).Return(nil)
+		repo.On("ClearEscalation", ctx, int64(5)).Return(nil)
+		pub := &capturePublisher{}
+		buffer := event.NewBuffer(pub, 100, time.Hour, testLogger)
+
+		report, err := NewService(repo, buffer, nil, clock.NewFake(testNow), testLogger).Escalate(ctx)
+
+		require.NoError(t, err)
+		assert.Equal(t, &EscalationReport{Reminders: 1, Tasks: 2, Reset: 2}, report)
+		repo.AssertExpectations(t)
+		require.NoError(t, buffer.Flush(ctx))
+		require.Len(t, pub.messages, 3)
+		assert.Equal(t, event.TypeLoanReminderDue, pub.messages[0].Type)
+		assert.Equal(t, event.LoanReminderDueEvent{
+			EventID: pub.messages[0].EventID, LoanID: 1, CustomerID: 11, DaysPastDue: 1, Step: 1, Channel: "sms", Timestamp: testNow,
+		}, pub.messages[0].Payload)
+		assert.Equal(t, int64(11), pub.messages[0].EntityID)
+		assert.Equal(t, event.TypeCollectionsTaskDue, pub.messages[1].Type)
+		assert.Equal(t, event.CollectionsTaskDueEvent{
+			EventID: pub.messages[1].EventID, LoanID: 3, CustomerID: 13, DaysPastDue: 16, Step: 14, Task: "CALL", Collector: "alice", Timestamp: testNow,
+		}, pub.messages[1].Payload)
+		assert.Equal(t, "LETTER", pub.messages[2].Payload.(event.CollectionsTaskDueEvent).Task)
+	})
+
+	t.Run("steps reached longer ago than the tenant's window send nothing", func(t *testing.T) {
+		repo := new(MockRepository)
+		repo.On("ListEscalationSteps", ctx).Return(defaultLadder, nil)
+		repo.On("ListEscalations", ctx).Return([]Escalation{
+			// Reached SMS 4 days ago, inside acme's 5 day window.
+			{LoanID: 1, TenantID: "acme", CustomerID: 11, LoanStatus: loan.StatusActive, DaysPastDue: 5},
+			// Reached email 6 days ago: stale for acme.
+			{LoanID: 2, TenantID: "acme", CustomerID: 12, LoanStatus: loan.StatusActive, DaysPastDue: 13},
+			// The same loan at globex, which has no window.
+			{LoanID: 3, TenantID: "globex", CustomerID: 13, LoanStatus: loan.StatusActive, DaysPastDue: 13},
+			// A call is a task, not a reminder: never stale.
+			{LoanID: 4, TenantID: "acme", CustomerID: 14, LoanStatus: loan.StatusDelinquent, DaysPastDue: 28, LastStep: step(7)},
+		}, nil)
+		repo.On("SetEscalation", ctx, int64(1), 1, testNow).Return(nil)
+		repo.On("SetEscalation", ctx, int64(2), 7, testNow).Return(nil)
+		repo.On("SetEscalation", ctx, int64(3), 7, testNow).Return(nil)
+		repo.On("SetEscalation", ctx, int64(4), 14, testNow).Return(nil)
+		windows := tenantWindows{"acme": 5}
+		pub := &capturePublisher{}
+		buffer := event.NewBuffer(pub, 100, time.Hour, testLogger)
+
+		report, err := NewService(repo, buffer, windows, clock.NewFake(testNow), testLogger).Escalate(ctx)
+
+		require.NoError(t, err)
+		assert.Equal(t, &EscalationReport{Reminders: 2, Tasks: 1, Stale: 1}, report)
+		repo.AssertExpectations(t)
+		require.NoError(t, buffer.Flush(ctx))
+		require.Len(t, pub.messages, 3)
+		assert.Equal(t, int64(1), pub.messages[0].Payload.(event.LoanReminderDueEvent).LoanID)
+		assert.Equal(t, int64(3), pub.messages[1].Payload.(event.LoanReminderDueEvent).LoanID)
+	})
+
+	t.Run("an empty ladder sends nothing", func(t *testing.T) {
+		repo := new(MockRepository)
+		repo.On("ListEscalationSteps", ctx).Return([]EscalationStep{}, nil)
+		repo.On("ListEscalations", ctx).Return([]Escalation{{LoanID: 1, LoanStatus: loan.StatusActive, DaysPastDue: 40}}, nil)
+
+		report, err := newTestService(repo).Escalate(ctx)
+
+		require.NoError(t, err)
+		assert.Equal(t, &EscalationReport{}, report)
+		repo.AssertNotCalled(t, "SetEscalation", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("stops when a step cannot be stored", func(t *testing.T) {
+		repo := new(MockRepository)
+		repo.On("ListEscalationSteps", ctx).Return(defaultLadder, nil)
+		repo.On("ListEscalations", ctx).Return([]Escalation{{LoanID: 1, LoanStatus: loan.StatusActive, DaysPastDue: 2}}, nil)
+		repo.On("SetEscalation", ctx, int64(1), 1, testNow).Return(apperrors.ErrDatabase)
+		pub := &capturePublisher{}
+		buffer := event.NewBuffer(pub, 100, time.Hour, testLogger)
+
+		_, err := NewService(repo, buffer, nil, clock.NewFake(testNow), testLogger).Escalate(ctx)
+
+		assert.ErrorIs(t, err, apperrors.ErrDatabase)
+		require.NoError(t, buffer.Flush(ctx))
+		assert.Empty(t, pub.messages)
+	})
+
+	t.Run("reports a ladder that cannot be read", func(t *testing.T) {
+		repo := new(MockRepository)
+		repo.On("ListEscalationSteps", ctx).Return(nil, errors.New("connection reset"))
+
+		_, err := newTestService(repo).Escalate(ctx)
+		assert.Error(t, err)
+	})
+}
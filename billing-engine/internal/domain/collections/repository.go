@@ -0,0 +1,71 @@
+package collections
+
+import (
+	"context"
+	"time"
+)
+
+type Repository interface {
+	CreateRule(ctx context.Context, rule *Rule) error
+
+	// ListRules returns every rule in the order they are tried: by priority,
+	// then by ID.
+	ListRules(ctx context.Context) ([]Rule, error)
+
+	// DeleteRule returns ErrNotFound for an unknown rule. Assignments made
+	// by the rule stay in their queues.
+	DeleteRule(ctx context.Context, ruleID int64) error
+
+	// ListCandidates returns the loans that are not paid off, have days past
+	// due and are not in an open assignment, by loan ID.
+	ListCandidates(ctx context.Context) ([]Candidate, error)
+
+	// CreateAssignment stores an open assignment. It returns
+	// ErrAlreadyExists when the loan is already in an open assignment.
+	CreateAssignment(ctx context.Context, assignment *Assignment) error
+
+	// GetAssignment returns ErrNotFound for an unknown assignment.
+	GetAssignment(ctx context.Context, assignmentID int64) (*Assignment, error)
+
+	// GetOpenAssignmentForLoan returns the loan's open assignment, or
+	// ErrNotFound while the loan is in none.
+	GetOpenAssignmentForLoan(ctx context.Context, loanID int64) (*Assignment, error)
+
+	// ListOpenAssignments returns the open assignments of collector, or of
+	// every collector when it is empty, with the current state of their
+	// loans. The longest past due come first.
+	ListOpenAssignments(ctx context.Context, collector string) ([]Assignment, error)
+
+	// ResolveAssignment closes the assignment only while it is still open,
+	// and returns ErrConflict otherwise.
+	ResolveAssignment(ctx context.Context, assignmentID int64, resolution Resolution, at time.Time) error
+
+	// Reassign moves an open assignment to collector and records action in
+	// the same transaction. It returns ErrNotFound for an unknown assignment
+	// and ErrConflict for a resolved one.
+	Reassign(ctx context.Context, assignmentID int64, collector string, action *Action) error
+
+	CreateAction(ctx context.Context, action *Action) error
+
+	// ListActions returns the actions on an assignment, newest first.
+	ListActions(ctx context.Context, assignmentID int64) ([]Action, error)
+
+	// ListEscalationSteps returns the reminder ladder by days past due.
+	ListEscalationSteps(ctx context.Context) ([]EscalationStep, error)
+
+	// ReplaceEscalationSteps stores steps as the whole ladder in one
+	// transaction and stamps their CreatedAt.
+	ReplaceEscalationSteps(ctx context.Context, steps []EscalationStep) error
+
+	// ListEscalations returns the loans that have days past due and are not
+	// paid off, and the loans that reached a step before, by loan ID.
+	ListEscalations(ctx context.Context) ([]Escalation, error)
+
+	// SetEscalation records that the loan reached the step at step days
+	// past due.
+	SetEscalation(ctx context.Context, loanID int64, step int, at time.Time) error
+
+	// ClearEscalation forgets the step the loan reached, so that it starts
+	// the ladder again from the bottom when it next falls behind.
+	ClearEscalation(ctx context.Context, loanID int64) error
+}
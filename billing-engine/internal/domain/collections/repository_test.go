@@ -0,0 +1,96 @@
+package collections
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) CreateRule(ctx context.Context, rule *Rule) error {
+	return m.Called(ctx, rule).Error(0)
+}
+
+func (m *MockRepository) ListRules(ctx context.Context) ([]Rule, error) {
+	args := m.Called(ctx)
+	rules, _ := args.Get(0).([]Rule)
+	return rules, args.Error(1)
+}
+
+func (m *MockRepository) DeleteRule(ctx context.Context, ruleID int64) error {
+	return m.Called(ctx, ruleID).Error(0)
+}
+
+func (m *MockRepository) ListCandidates(ctx context.Context) ([]Candidate, error) {
+	args := m.Called(ctx)
+	candidates, _ := args.Get(0).([]Candidate)
+	return candidates, args.Error(1)
+}
+
+func (m *MockRepository) CreateAssignment(ctx context.Context, assignment *Assignment) error {
+	return m.Called(ctx, assignment).Error(0)
+}
+
+func (m *MockRepository) GetAssignment(ctx context.Context, assignmentID int64) (*Assignment, error) {
+	args := m.Called(ctx, assignmentID)
+	assignment, _ := args.Get(0).(*Assignment)
+	return assignment, args.Error(1)
+}
+
+func (m *MockRepository) GetOpenAssignmentForLoan(ctx context.Context, loanID int64) (*Assignment, error) {
+	args := m.Called(ctx, loanID)
+	assignment, _ := args.Get(0).(*Assignment)
+	return assignment, args.Error(1)
+}
+
+func (m *MockRepository) ListOpenAssignments(ctx context.Context, collector string) ([]Assignment, error) {
+	args := m.Called(ctx, collector)
+	assignments, _ := args.Get(0).([]Assignment)
+	return assignments, args.Error(1)
+}
+
+func (m *MockRepository) ResolveAssignment(ctx context.Context, assignmentID int64, resolution Resolution, at time.Time) error {
+	return m.Called(ctx, assignmentID, resolution, at).Error(0)
+}
+
+func (m *MockRepository) Reassign(ctx context.Context, assignmentID int64, collector string, action *Action) error {
+	return m.Called(ctx, assignmentID, collector, action).Error(0)
+}
+
+func (m *MockRepository) CreateAction(ctx context.Context, action *Action) error {
+	return m.Called(ctx, action).Error(0)
+}
+
+func (m *MockRepository) ListActions(ctx context.Context, assignmentID int64) ([]Action, error) {
+	args := m.Called(ctx, assignmentID)
+	actions, _ := args.Get(0).([]Action)
+	return actions, args.Error(1)
+}
+
+func (m *MockRepository) ListEscalationSteps(ctx context.Context) ([]EscalationStep, error) {
+	args := m.Called(ctx)
+	steps, _ := args.Get(0).([]EscalationStep)
+	return steps, args.Error(1)
+}
+
+func (m *MockRepository) ReplaceEscalationSteps(ctx context.Context, steps []EscalationStep) error {
+	return m.Called(ctx, steps).Error(0)
+}
+
+func (m *MockRepository) ListEscalations(ctx context.Context) ([]Escalation, error) {
+	args := m.Called(ctx)
+	escalations, _ := args.Get(0).([]Escalation)
+	return escalations, args.Error(1)
+}
+
+func (m *MockRepository) SetEscalation(ctx context.Context, loanID int64, step int, at time.Time) error {
+	return m.Called(ctx, loanID, step, at).Error(0)
+}
+
+func (m *MockRepository) ClearEscalation(ctx context.Context, loanID int64) error {
+	return m.Called(ctx, loanID).Error(0)
+}
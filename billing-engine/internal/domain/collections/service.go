@@ -0,0 +1,464 @@
+package collections
+
+import (
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/event"
+	"billing-engine/internal/infrastructure/monitoring"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"billing-engine/internal/pkg/scope"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+type Service interface {
+	CreateRule(ctx context.Context, rule *Rule) (*Rule, error)
+	ListRules(ctx context.Context) ([]Rule, error)
+	DeleteRule(ctx context.Context, ruleID int64) error
+
+	// ListQueue returns the open assignments of collector.
+	ListQueue(ctx context.Context, collector string) ([]Assignment, error)
+
+	// GetOpenAssignment returns the open assignment of the loan, or nil
+	// while the loan is in none.
+	GetOpenAssignment(ctx context.Context, loanID int64) (*Assignment, error)
+
+	// RecordAction records what collector did about an open assignment.
+	RecordAction(ctx context.Context, assignmentID int64, collector string, action *Action) (*Action, error)
+	ListActions(ctx context.Context, assignmentID int64) ([]Action, error)
+
+	// Reassign moves an open assignment to another collector. by and reason
+	// are kept as a REASSIGNED action.
+	Reassign(ctx context.Context, assignmentID int64, collector, by, reason string) (*Assignment, error)
+
+	// Assign resolves the open assignments whose loans are no longer past
+	// due, routes every unassigned past-due loan through the rules and
+	// refreshes the queue size metrics. Loans no rule matches stay
+	// unassigned until a rule does.
+	Assign(ctx context.Context) (*RunReport, error)
+
+	// ListEscalationSteps returns the reminder ladder by days past due.
+	ListEscalationSteps(ctx context.Context) ([]EscalationStep, error)
+
+	// ReplaceEscalationSteps validates steps and makes them the whole
+	// ladder. An empty ladder sends no reminders. Loans keep the step they
+	// reached, so a new step below it does not fire for them.
+	ReplaceEscalationSteps(ctx context.Context, steps []EscalationStep) ([]EscalationStep, error)
+
+	// Escalate moves every past-due loan up the ladder. A loan that reached
+	// a higher step than last time raises a loan.reminder.due event for an
+	// SMS or EMAIL step and a collections.task.due event for a CALL or
+	// LETTER step. An SMS or EMAIL step the loan reached longer ago than
+	// its tenant's reminder window is raised without an event. Loans that
+	// are current or paid off again start the ladder from the bottom the
+	// next time they fall behind.
+	Escalate(ctx context.Context) (*EscalationReport, error)
+}
+
+var _ Service = (*service)(nil)
+
+type service struct {
+	repo      Repository
+	reminders *event.Buffer
+	windows   ReminderWindowSource
+	clock     clock.Clock
+	logger    *slog.Logger
+}
+
+// NewService wires the collections service. Escalation events go out
+// through reminders, which batches them; a nil buffer sends none but still
+// moves loans up the ladder. windows gives each tenant's reminder window and
+// nil means none. The clock stamps assignments, resolutions and escalations
+// and nil means the wall clock.
+func NewService(repo Repository, reminders *event.Buffer, windows ReminderWindowSource, clk clock.Clock, logger *slog.Logger) Service {
+	if repo == nil {
+		panic("collections repository cannot be nil")
+	}
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+		logger.Warn("Warning: No logger provided to collections.NewService, using default stderr handler")
+	}
+	return &service{
+		repo:      repo,
+		reminders: reminders,
+		windows:   windows,
+		clock:     clock.OrSystem(clk),
+		logger:    logger.With(slog.String("component", "collectionsService")),
+	}
+}
+
+func (s *service) CreateRule(ctx context.Context, rule *Rule) (*Rule, error) {
+	if rule == nil {
+		return nil, fmt.Errorf("%w: rule cannot be nil", apperrors.ErrInvalidArgument)
+	}
+	r := *rule
+	r.normalize()
+	r.Active = true
+	if err := r.validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err)
+	}
+
+	if err := s.repo.CreateRule(ctx, &r); err != nil {
+		s.logger.ErrorContext(ctx, "Failed to create collection rule", slog.String("name", r.Name), slog.Any("error", err))
+		return nil, fmt.Errorf("failed to create collection rule: %w", err)
+	}
+	s.logger.InfoContext(ctx, "Collection rule created", slog.Int64("ruleID", r.ID), slog.String("collector", r.Collector))
+	return &r, nil
+}
+
+func (s *service) ListRules(ctx context.Context) ([]Rule, error) {
+	rules, err := s.repo.ListRules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list collection rules: %w", err)
+	}
+	return rules, nil
+}
+
+func (s *service) DeleteRule(ctx context.Context, ruleID int64) error {
+	if ruleID <= 0 {
+		return fmt.Errorf("%w: rule ID must be a positive number", apperrors.ErrInvalidArgument)
+	}
+	if err := s.repo.DeleteRule(ctx, ruleID); err != nil {
+		return fmt.Errorf("failed to delete collection rule %d: %w", ruleID, err)
+	}
+	s.logger.InfoContext(ctx, "Collection rule deleted", slog.Int64("ruleID", ruleID))
+	return nil
+}
+
+func (s *service) ListQueue(ctx context.Context, collector string) ([]Assignment, error) {
+	collector = strings.TrimSpace(collector)
+	if collector == "" {
+		return nil, fmt.Errorf("%w: collector cannot be empty", apperrors.ErrInvalidArgument)
+	}
+	queue, err := s.repo.ListOpenAssignments(ctx, collector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the queue of %s: %w", collector, err)
+	}
+	return queue, nil
+}
+
+func (s *service) GetOpenAssignment(ctx context.Context, loanID int64) (*Assignment, error) {
+	assignment, err := s.repo.GetOpenAssignmentForLoan(ctx, loanID)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the open assignment of loan %d: %w", loanID, err)
+	}
+	return assignment, nil
+}
+
+func (s *service) RecordAction(ctx context.Context, assignmentID int64, collector string, action *Action) (*Action, error) {
+	if action == nil {
+		return nil, fmt.Errorf("%w: action cannot be nil", apperrors.ErrInvalidArgument)
+	}
+	a := *action
+	a.normalize()
+	a.Collector = strings.TrimSpace(collector)
+	if err := a.validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err)
+	}
+	assignment, err := s.openAssignment(ctx, assignmentID)
+	if err != nil {
+		return nil, err
+	}
+	a.AssignmentID = assignment.ID
+	a.LoanID = assignment.LoanID
+
+	if err := s.repo.CreateAction(ctx, &a); err != nil {
+		s.logger.ErrorContext(ctx, "Failed to record collection action", slog.Int64("assignmentID", assignmentID), slog.Any("error", err))
+		return nil, fmt.Errorf("failed to record action on assignment %d: %w", assignmentID, err)
+	}
+	monitoring.RecordCollectionsAction(string(a.Type))
+	return &a, nil
+}
+
+func (s *service) ListActions(ctx context.Context, assignmentID int64) ([]Action, error) {
+	if assignmentID <= 0 {
+		return nil, fmt.Errorf("%w: assignment ID must be a positive number", apperrors.ErrInvalidArgument)
+	}
+	if _, err := s.repo.GetAssignment(ctx, assignmentID); err != nil {
+		return nil, fmt.Errorf("failed to get assignment %d: %w", assignmentID, err)
+	}
+	actions, err := s.repo.ListActions(ctx, assignmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list actions of assignment %d: %w", assignmentID, err)
+	}
+	return actions, nil
+}
+
+func (s *service) Reassign(ctx context.Context, assignmentID int64, collector, by, reason string) (*Assignment, error) {
+	collector = strings.TrimSpace(collector)
+	reason = strings.TrimSpace(reason)
+	switch {
+	case collector == "":
+		return nil, fmt.Errorf("%w: collector cannot be empty", apperrors.ErrInvalidArgument)
+	case len(collector) > MaxCollectorLength:
+		return nil, fmt.Errorf("%w: collector cannot exceed %d characters", apperrors.ErrInvalidArgument, MaxCollectorLength)
+	case len(reason) > MaxActionNoteBytes:
+		return nil, fmt.Errorf("%w: reason cannot exceed %d bytes", apperrors.ErrInvalidArgument, MaxActionNoteBytes)
+	}
+	assignment, err := s.openAssignment(ctx, assignmentID)
+	if err != nil {
+		return nil, err
+	}
+	if assignment.Collector == collector {
+		return assignment, nil
+	}
+
+	note := fmt.Sprintf("from %s to %s", assignment.Collector, collector)
+	if reason != "" {
+		note += ": " + reason
+	}
+	action := &Action{
+		AssignmentID: assignment.ID,
+		LoanID:       assignment.LoanID,
+		Collector:    strings.TrimSpace(by),
+		Type:         ActionReassigned,
+		Note:         note,
+	}
+	if err := s.repo.Reassign(ctx, assignment.ID, collector, action); err != nil {
+		return nil, fmt.Errorf("failed to reassign assignment %d: %w", assignmentID, err)
+	}
+	s.logger.InfoContext(ctx, "Collection assignment reassigned", slog.Int64("assignmentID", assignment.ID),
+		slog.String("from", assignment.Collector), slog.String("to", collector))
+	assignment.Collector = collector
+	return assignment, nil
+}
+
+// openAssignment returns ErrConflict for a resolved assignment.
+func (s *service) openAssignment(ctx context.Context, assignmentID int64) (*Assignment, error) {
+	if assignmentID <= 0 {
+		return nil, fmt.Errorf("%w: assignment ID must be a positive number", apperrors.ErrInvalidArgument)
+	}
+	assignment, err := s.repo.GetAssignment(ctx, assignmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get assignment %d: %w", assignmentID, err)
+	}
+	if assignment.Status != AssignmentOpen {
+		return nil, fmt.Errorf("%w: assignment %d is already resolved", apperrors.ErrConflict, assignmentID)
+	}
+	return assignment, nil
+}
+
+func (s *service) Assign(ctx context.Context) (*RunReport, error) {
+	report := &RunReport{}
+	now := s.clock.Now()
+	sizes := map[string]map[string]int{}
+	count := func(collector string, daysPastDue int) {
+		if sizes[collector] == nil {
+			sizes[collector] = map[string]int{}
+		}
+		sizes[collector][loan.DPDBucket(daysPastDue)]++
+		report.Open++
+	}
+
+	open, err := s.repo.ListOpenAssignments(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list open assignments: %w", err)
+	}
+	for _, a := range open {
+		resolution := resolutionOf(a)
+		if resolution == "" {
+			count(a.Collector, a.DaysPastDue)
+			continue
+		}
+		err := s.repo.ResolveAssignment(ctx, a.ID, resolution, now)
+		if errors.Is(err, apperrors.ErrConflict) {
+			// Resolved by a concurrent run.
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve assignment %d: %w", a.ID, err)
+		}
+		monitoring.RecordCollectionsResolution(string(resolution), now.Sub(a.AssignedAt))
+		report.Resolved++
+	}
+
+	rules, err := s.repo.ListRules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list collection rules: %w", err)
+	}
+	candidates, err := s.repo.ListCandidates(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list loans to assign: %w", err)
+	}
+	for _, c := range candidates {
+		rule := firstMatch(rules, c)
+		if rule == nil {
+			report.Unmatched++
+			continue
+		}
+		ruleID := rule.ID
+		assignment := &Assignment{
+			LoanID:     c.LoanID,
+			CustomerID: c.CustomerID,
+			Collector:  rule.Collector,
+			RuleID:     &ruleID,
+			Status:     AssignmentOpen,
+			AssignedAt: now,
+		}
+		err := s.repo.CreateAssignment(ctx, assignment)
+		if errors.Is(err, apperrors.ErrAlreadyExists) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to assign loan %d: %w", c.LoanID, err)
+		}
+		count(rule.Collector, c.DaysPastDue)
+		report.Assigned++
+	}
+
+	monitoring.SetCollectionsQueueSizes(sizes)
+	s.logger.InfoContext(ctx, "Collection assignment run finished",
+		slog.Int("resolved", report.Resolved), slog.Int("assigned", report.Assigned),
+		slog.Int("unmatched", report.Unmatched), slog.Int("open", report.Open))
+	return report, nil
+}
+
+// resolutionOf returns how an open assignment resolved, or "" while its loan
+// is still past due.
+func resolutionOf(a Assignment) Resolution {
+	switch {
+	case a.LoanStatus == loan.StatusPaidOff:
+		return ResolutionPaidOff
+	case a.DaysPastDue == 0:
+		return ResolutionCured
+	}
+	return ""
+}
+
+func firstMatch(rules []Rule, c Candidate) *Rule {
+	for i := range rules {
+		if rules[i].Matches(c) {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+func (s *service) ListEscalationSteps(ctx context.Context) ([]EscalationStep, error) {
+	steps, err := s.repo.ListEscalationSteps(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list escalation steps: %w", err)
+	}
+	return steps, nil
+}
+
+func (s *service) ReplaceEscalationSteps(ctx context.Context, steps []EscalationStep) ([]EscalationStep, error) {
+	ladder, err := normalizeLadder(steps)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err)
+	}
+	if err := s.repo.ReplaceEscalationSteps(ctx, ladder); err != nil {
+		s.logger.ErrorContext(ctx, "Failed to replace escalation steps", slog.Any("error", err))
+		return nil, fmt.Errorf("failed to replace escalation steps: %w", err)
+	}
+	s.logger.InfoContext(ctx, "Escalation ladder replaced", slog.Int("steps", len(ladder)))
+	return ladder, nil
+}
+
+func (s *service) Escalate(ctx context.Context) (*EscalationReport, error) {
+	report := &EscalationReport{}
+	now := s.clock.Now()
+
+	ladder, err := s.repo.ListEscalationSteps(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list escalation steps: %w", err)
+	}
+	escalations, err := s.repo.ListEscalations(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list loans to escalate: %w", err)
+	}
+	// Each tenant's window is read once, so that the whole run uses the
+	// same one.
+	windows := make(map[string]int)
+
+	for _, e := range escalations {
+		if e.DaysPastDue == 0 || e.LoanStatus == loan.StatusPaidOff {
+			if e.LastStep == nil {
+				continue
+			}
+			if err := s.repo.ClearEscalation(ctx, e.LoanID); err != nil {
+				return nil, fmt.Errorf("failed to reset the escalation of loan %d: %w", e.LoanID, err)
+			}
+			report.Reset++
+			continue
+		}
+		step, ok := stepFor(ladder, e.DaysPastDue)
+		if !ok || (e.LastStep != nil && *e.LastStep >= step.DaysPastDue) {
+			continue
+		}
+		window, err := s.reminderWindow(ctx, windows, e.TenantID)
+		if err != nil {
+			return nil, err
+		}
+		// The step is stored before its event is queued: a failed publish
+		// stays in the event log for a replay, while a failed store would
+		// send the same reminder again tomorrow.
+		if err := s.repo.SetEscalation(ctx, e.LoanID, step.DaysPastDue, now); err != nil {
+			return nil, fmt.Errorf("failed to escalate loan %d: %w", e.LoanID, err)
+		}
+		if stale(step, e, window) {
+			report.Stale++
+			continue
+		}
+		if s.reminders != nil {
+			s.reminders.Add(ctx, escalationMessage(e, step, now))
+		}
+		if step.Notifies() {
+			report.Reminders++
+		} else {
+			report.Tasks++
+		}
+		monitoring.RecordCollectionsEscalation(string(step.Action))
+	}
+
+	s.logger.InfoContext(ctx, "Escalation run finished",
+		slog.Int("reminders", report.Reminders), slog.Int("tasks", report.Tasks), slog.Int("reset", report.Reset), slog.Int("stale", report.Stale))
+	return report, nil
+}
+
+// reminderWindow returns the tenant's reminder window from windows, reading
+// it on first use.
+func (s *service) reminderWindow(ctx context.Context, windows map[string]int, tenantID string) (int, error) {
+	if s.windows == nil {
+		return 0, nil
+	}
+	if window, ok := windows[tenantID]; ok {
+		return window, nil
+	}
+	window, err := s.windows.ReminderWindow(scope.WithTenant(ctx, tenantID))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read the reminder window of tenant %q: %w", tenantID, err)
+	}
+	windows[tenantID] = window
+	return window, nil
+}
+
+func escalationMessage(e Escalation, step EscalationStep, now time.Time) event.Message {
+	if step.Notifies() {
+		return event.ReminderDueMessage(event.LoanReminderDueEvent{
+			LoanID:      e.LoanID,
+			CustomerID:  e.CustomerID,
+			DaysPastDue: e.DaysPastDue,
+			Step:        step.DaysPastDue,
+			Channel:     step.Channel(),
+			Timestamp:   now,
+		})
+	}
+	return event.CollectionsTaskDueMessage(event.CollectionsTaskDueEvent{
+		LoanID:      e.LoanID,
+		CustomerID:  e.CustomerID,
+		DaysPastDue: e.DaysPastDue,
+		Step:        step.DaysPastDue,
+		Task:        string(step.Action),
+		Collector:   e.Collector,
+		Timestamp:   now,
+	})
+}
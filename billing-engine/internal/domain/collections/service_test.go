@@ -0,0 +1,219 @@
+package collections
+
+import (
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/infrastructure/monitoring"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+var testNow = time.Date(2025, 2, 3, 2, 30, 0, 0, time.UTC)
+
+func newTestService(repo *MockRepository) Service {
+	return NewService(repo, nil, nil, clock.NewFake(testNow), testLogger)
+}
+
+func money(m loan.Money) *loan.Money {
+	return &m
+}
+
+func TestRuleMatches(t *testing.T) {
+	candidate := Candidate{LoanID: 1, Address: "12 Jalan Sudirman, Jakarta", DaysPastDue: 45, Outstanding: 2_000_000}
+
+	tests := []struct {
+		name string
+		rule Rule
+		want bool
+	}{
+		{name: "catch-all", rule: Rule{Active: true}, want: true},
+		{name: "inactive", rule: Rule{}, want: false},
+		{name: "bucket", rule: Rule{Active: true, Bucket: "31-60"}, want: true},
+		{name: "other bucket", rule: Rule{Active: true, Bucket: "1-30"}, want: false},
+		{name: "region ignores case", rule: Rule{Active: true, Region: "JAKARTA"}, want: true},
+		{name: "other region", rule: Rule{Active: true, Region: "Surabaya"}, want: false},
+		{name: "balance in range", rule: Rule{Active: true, MinOutstanding: money(1_000_000), MaxOutstanding: money(2_000_000)}, want: true},
+		{name: "balance below", rule: Rule{Active: true, MinOutstanding: money(5_000_000)}, want: false},
+		{name: "balance above", rule: Rule{Active: true, MaxOutstanding: money(1_999_999)}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.rule.Matches(candidate))
+		})
+	}
+}
+
+func TestServiceCreateRule(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("stores an active rule", func(t *testing.T) {
+		repo := new(MockRepository)
+		repo.On("CreateRule", ctx, mock.MatchedBy(func(r *Rule) bool {
+			r.ID = 4
+			return r.Name == "Jakarta early" && r.Collector == "alice" && r.Bucket == "1-30" && r.Active
+		})).Return(nil)
+
+		created, err := newTestService(repo).CreateRule(ctx, &Rule{Name: " Jakarta early ", Collector: "alice ", Bucket: "1-30", Region: "Jakarta"})
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(4), created.ID)
+		repo.AssertExpectations(t)
+	})
+
+	for name, rule := range map[string]Rule{
+		"no name":          {Collector: "alice"},
+		"no collector":     {Name: "r"},
+		"current bucket":   {Name: "r", Collector: "alice", Bucket: "current"},
+		"unknown bucket":   {Name: "r", Collector: "alice", Bucket: "0-7"},
+		"inverted balance": {Name: "r", Collector: "alice", MinOutstanding: money(10), MaxOutstanding: money(5)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := newTestService(new(MockRepository)).CreateRule(ctx, &rule)
+			assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+		})
+	}
+}
+
+func TestServiceRecordAction(t *testing.T) {
+	ctx := context.Background()
+	open := &Assignment{ID: 9, LoanID: 3, Collector: "alice", Status: AssignmentOpen}
+
+	t.Run("records on the assigned loan", func(t *testing.T) {
+		repo := new(MockRepository)
+		repo.On("GetAssignment", ctx, int64(9)).Return(open, nil)
+		repo.On("CreateAction", ctx, mock.MatchedBy(func(a *Action) bool {
+			return a.AssignmentID == 9 && a.LoanID == 3 && a.Collector == "alice" && a.Type == ActionCall
+		})).Return(nil)
+
+		action, err := newTestService(repo).RecordAction(ctx, 9, "alice", &Action{Type: "call", Note: "no answer"})
+
+		require.NoError(t, err)
+		assert.Equal(t, ActionCall, action.Type)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("promise to pay needs amount and date", func(t *testing.T) {
+		_, err := newTestService(new(MockRepository)).RecordAction(ctx, 9, "alice", &Action{Type: ActionPromiseToPay})
+		assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+	})
+
+	t.Run("reassignments are not recorded directly", func(t *testing.T) {
+		_, err := newTestService(new(MockRepository)).RecordAction(ctx, 9, "alice", &Action{Type: ActionReassigned})
+		assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+	})
+
+	t.Run("resolved assignment", func(t *testing.T) {
+		repo := new(MockRepository)
+		repo.On("GetAssignment", ctx, int64(9)).Return(&Assignment{ID: 9, Status: AssignmentResolved}, nil)
+
+		_, err := newTestService(repo).RecordAction(ctx, 9, "alice", &Action{Type: ActionSMS})
+
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+	})
+}
+
+func TestServiceGetOpenAssignment(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("returns the open assignment", func(t *testing.T) {
+		repo := new(MockRepository)
+		repo.On("GetOpenAssignmentForLoan", ctx, int64(3)).Return(&Assignment{ID: 9, LoanID: 3}, nil)
+
+		a, err := newTestService(repo).GetOpenAssignment(ctx, 3)
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(9), a.ID)
+	})
+
+	t.Run("nil while the loan is in none", func(t *testing.T) {
+		repo := new(MockRepository)
+		repo.On("GetOpenAssignmentForLoan", ctx, int64(3)).Return(nil, apperrors.ErrNotFound)
+
+		a, err := newTestService(repo).GetOpenAssignment(ctx, 3)
+
+		require.NoError(t, err)
+		assert.Nil(t, a)
+	})
+}
+
+func TestServiceReassign(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("moves the loan and records who did it", func(t *testing.T) {
+		repo := new(MockRepository)
+		repo.On("GetAssignment", ctx, int64(9)).Return(&Assignment{ID: 9, LoanID: 3, Collector: "alice", Status: AssignmentOpen}, nil)
+		repo.On("Reassign", ctx, int64(9), "bob", mock.MatchedBy(func(a *Action) bool {
+			return a.Type == ActionReassigned && a.Collector == "supervisor" && a.Note == "from alice to bob: alice is on leave"
+		})).Return(nil)
+
+		assignment, err := newTestService(repo).Reassign(ctx, 9, " bob ", "supervisor", "alice is on leave")
+
+		require.NoError(t, err)
+		assert.Equal(t, "bob", assignment.Collector)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("same collector is a no-op", func(t *testing.T) {
+		repo := new(MockRepository)
+		repo.On("GetAssignment", ctx, int64(9)).Return(&Assignment{ID: 9, Collector: "alice", Status: AssignmentOpen}, nil)
+
+		_, err := newTestService(repo).Reassign(ctx, 9, "alice", "supervisor", "")
+
+		require.NoError(t, err)
+		repo.AssertNotCalled(t, "Reassign", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("needs a collector", func(t *testing.T) {
+		_, err := newTestService(new(MockRepository)).Reassign(ctx, 9, " ", "supervisor", "")
+		assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+	})
+}
+
+func TestServiceAssign(t *testing.T) {
+	ctx := context.Background()
+	assignedAt := testNow.AddDate(0, 0, -10)
+	repo := new(MockRepository)
+	repo.On("ListOpenAssignments", ctx, "").Return([]Assignment{
+		{ID: 1, LoanID: 11, Collector: "alice", Status: AssignmentOpen, LoanStatus: loan.StatusActive, DaysPastDue: 0, AssignedAt: assignedAt},
+		{ID: 2, LoanID: 12, Collector: "alice", Status: AssignmentOpen, LoanStatus: loan.StatusPaidOff, AssignedAt: assignedAt},
+		{ID: 3, LoanID: 13, Collector: "bob", Status: AssignmentOpen, LoanStatus: loan.StatusDelinquent, DaysPastDue: 70},
+	}, nil)
+	repo.On("ResolveAssignment", ctx, int64(1), ResolutionCured, testNow).Return(nil)
+	repo.On("ResolveAssignment", ctx, int64(2), ResolutionPaidOff, testNow).Return(nil)
+	repo.On("ListRules", ctx).Return([]Rule{
+		{ID: 7, Collector: "carol", Bucket: "90+", Active: true},
+		{ID: 8, Collector: "dan", Region: "bandung", Active: true},
+		{ID: 9, Collector: "erin", MinOutstanding: money(1_000_000), Active: true},
+	}, nil)
+	repo.On("ListCandidates", ctx).Return([]Candidate{
+		{LoanID: 21, CustomerID: 31, Address: "Bandung", DaysPastDue: 120, Outstanding: 50},
+		{LoanID: 22, CustomerID: 32, Address: "Jl. Asia Afrika, Bandung", DaysPastDue: 8, Outstanding: 50},
+		{LoanID: 23, CustomerID: 33, Address: "Medan", DaysPastDue: 8, Outstanding: 50},
+	}, nil)
+	repo.On("CreateAssignment", ctx, mock.MatchedBy(func(a *Assignment) bool {
+		return a.LoanID == 21 && a.CustomerID == 31 && a.Collector == "carol" && *a.RuleID == 7 && a.AssignedAt.Equal(testNow)
+	})).Return(nil)
+	repo.On("CreateAssignment", ctx, mock.MatchedBy(func(a *Assignment) bool {
+		return a.LoanID == 22 && a.Collector == "dan" && *a.RuleID == 8
+	})).Return(nil)
+
+	report, err := newTestService(repo).Assign(ctx)
+
+	require.NoError(t, err)
+	assert.Equal(t, &RunReport{Resolved: 2, Assigned: 2, Unmatched: 1, Open: 3}, report)
+	repo.AssertExpectations(t)
+	assert.Equal(t, 1.0, testutil.ToFloat64(monitoring.Collections.QueueSize.WithLabelValues("bob", "61-90")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(monitoring.Collections.QueueSize.WithLabelValues("carol", "90+")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(monitoring.Collections.QueueSize.WithLabelValues("dan", "1-30")))
+}
@@ -0,0 +1,116 @@
+package contact
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"math/big"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type Type string
+
+const (
+	TypePhone Type = "phone"
+	TypeEmail Type = "email"
+
+	MaxValueLength = 254
+
+	// CodeLength is the number of digits of a verification code, CodeTTL
+	// how long it can be confirmed and MaxCodeAttempts how many wrong codes
+	// cancel it.
+	CodeLength      = 6
+	CodeTTL         = 15 * time.Minute
+	MaxCodeAttempts = 5
+
+	// ResendInterval is how long a customer waits before a new code is
+	// sent to the same contact.
+	ResendInterval = time.Minute
+)
+
+// e164 is a phone number in international form: a plus sign and up to 15
+// digits, the first of them the country code.
+var e164 = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+// Contact is a phone number or email address a customer can be reached at.
+// Only verified contacts are sent to. At most one contact per type is
+// Primary, and only a verified one.
+type Contact struct {
+	ID         int64
+	CustomerID int64
+	Type       Type
+	Value      string
+	Verified   bool
+	Primary    bool
+	VerifiedAt *time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// Verification is the code pending for a contact. Only the code's hash is
+// stored; the code itself goes out once, in the event notify-service sends.
+// Value is what the code was sent to, so that a code sent before the
+// contact's value changed cannot verify the new one.
+type Verification struct {
+	ContactID int64
+	Value     string
+	CodeHash  string
+	ExpiresAt time.Time
+	Attempts  int
+	SentAt    time.Time
+}
+
+// Normalize cleans value up as a contact of type t and rejects what cannot
+// be sent to. Phone numbers lose spaces, dashes, dots and parentheses and
+// must then be in E.164 form; email addresses are lower-cased and must be a
+// bare address, without a display name.
+func Normalize(t Type, value string) (string, error) {
+	value = strings.TrimSpace(value)
+	switch t {
+	case TypePhone:
+		value = strings.Map(func(r rune) rune {
+			switch r {
+			case ' ', '-', '.', '(', ')':
+				return -1
+			}
+			return r
+		}, value)
+		if !e164.MatchString(value) {
+			return "", fmt.Errorf("phone number must be in international form, such as +6281234567890")
+		}
+	case TypeEmail:
+		value = strings.ToLower(value)
+		addr, err := mail.ParseAddress(value)
+		if err != nil || addr.Address != value || len(value) > MaxValueLength {
+			return "", fmt.Errorf("email must be an address such as name@example.com")
+		}
+	default:
+		return "", fmt.Errorf("contact type must be %q or %q", TypePhone, TypeEmail)
+	}
+	return value, nil
+}
+
+// NewCode returns a random code of CodeLength digits.
+func NewCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(math.Pow10(CodeLength))))
+	if err != nil {
+		return "", fmt.Errorf("failed to generate verification code: %w", err)
+	}
+	return fmt.Sprintf("%0*d", CodeLength, n.Int64()), nil
+}
+
+func HashCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// Matches reports whether code is the one v was sent with.
+func (v *Verification) Matches(code string) bool {
+	return subtle.ConstantTimeCompare([]byte(HashCode(strings.TrimSpace(code))), []byte(v.CodeHash)) == 1
+}
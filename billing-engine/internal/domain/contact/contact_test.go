@@ -0,0 +1,53 @@
+package contact
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		t       Type
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"phone with separators", TypePhone, " +62 (812) 3456-7890 ", "+6281234567890", false},
+		{"phone without country code", TypePhone, "081234567890", "", true},
+		{"phone too short", TypePhone, "+62812", "", true},
+		{"email is lower-cased", TypeEmail, " Ayu.Lestari@Example.com", "ayu.lestari@example.com", false},
+		{"email with display name", TypeEmail, "Ayu <ayu@example.com>", "", true},
+		{"not an email", TypeEmail, "ayu.example.com", "", true},
+		{"unknown type", Type("fax"), "+6281234567890", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Normalize(tt.t, tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNewCode(t *testing.T) {
+	code, err := NewCode()
+
+	require.NoError(t, err)
+	assert.Regexp(t, `^[0-9]{6}$`, code)
+}
+
+func TestVerificationMatches(t *testing.T) {
+	v := &Verification{CodeHash: HashCode("012345"), ExpiresAt: time.Now().Add(CodeTTL)}
+
+	assert.True(t, v.Matches(" 012345 "))
+	assert.False(t, v.Matches("12345"))
+	assert.NotContains(t, v.CodeHash, "012345", "only the hash is kept")
+}
@@ -0,0 +1,35 @@
+package contact
+
+import "context"
+
+type Repository interface {
+	// Create stores c, which is never verified or primary yet. It returns
+	// ErrNotFound for an unknown customer and ErrConflict when the customer
+	// already has a contact of the same type and value.
+	Create(ctx context.Context, c *Contact) error
+
+	// List returns the customer's contacts by type, the primary one first.
+	List(ctx context.Context, customerID int64) ([]Contact, error)
+
+	Get(ctx context.Context, customerID, contactID int64) (*Contact, error)
+
+	// Update stores the value, verification and primary flag of c. A primary
+	// c takes the flag from the customer's other contact of its type in the
+	// same transaction.
+	Update(ctx context.Context, c *Contact) error
+
+	// Delete removes the contact along with its pending verification.
+	Delete(ctx context.Context, customerID, contactID int64) error
+
+	// SaveVerification replaces the contact's pending verification with v.
+	SaveVerification(ctx context.Context, v *Verification) error
+
+	// GetVerification returns ErrNotFound when no code is pending.
+	GetVerification(ctx context.Context, contactID int64) (*Verification, error)
+
+	// CountFailedAttempt adds a wrong code to the pending verification and
+	// returns the attempts made so far.
+	CountFailedAttempt(ctx context.Context, contactID int64) (int, error)
+
+	DeleteVerification(ctx context.Context, contactID int64) error
+}
@@ -0,0 +1,54 @@
+package contact
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) Create(ctx context.Context, c *Contact) error {
+	return m.Called(ctx, c).Error(0)
+}
+
+func (m *MockRepository) List(ctx context.Context, customerID int64) ([]Contact, error) {
+	args := m.Called(ctx, customerID)
+	contacts, _ := args.Get(0).([]Contact)
+	return contacts, args.Error(1)
+}
+
+func (m *MockRepository) Get(ctx context.Context, customerID, contactID int64) (*Contact, error) {
+	args := m.Called(ctx, customerID, contactID)
+	c, _ := args.Get(0).(*Contact)
+	return c, args.Error(1)
+}
+
+func (m *MockRepository) Update(ctx context.Context, c *Contact) error {
+	return m.Called(ctx, c).Error(0)
+}
+
+func (m *MockRepository) Delete(ctx context.Context, customerID, contactID int64) error {
+	return m.Called(ctx, customerID, contactID).Error(0)
+}
+
+func (m *MockRepository) SaveVerification(ctx context.Context, v *Verification) error {
+	return m.Called(ctx, v).Error(0)
+}
+
+func (m *MockRepository) GetVerification(ctx context.Context, contactID int64) (*Verification, error) {
+	args := m.Called(ctx, contactID)
+	v, _ := args.Get(0).(*Verification)
+	return v, args.Error(1)
+}
+
+func (m *MockRepository) CountFailedAttempt(ctx context.Context, contactID int64) (int, error) {
+	args := m.Called(ctx, contactID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockRepository) DeleteVerification(ctx context.Context, contactID int64) error {
+	return m.Called(ctx, contactID).Error(0)
+}
@@ -0,0 +1,282 @@
+package contact
+
+import (
+	"billing-engine/internal/event"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+type Service interface {
+	ListContacts(ctx context.Context, customerID int64) ([]Contact, error)
+	// AddContact stores a new contact. It is not sent to before it is
+	// verified with SendVerification and ConfirmVerification.
+	AddContact(ctx context.Context, customerID int64, t Type, value string) (*Contact, error)
+	// UpdateContact replaces the contact's value and primary flag. A
+	// changed value has to be verified again, and only a verified contact
+	// can be primary.
+	UpdateContact(ctx context.Context, customerID, contactID int64, value string, primary bool) (*Contact, error)
+	DeleteContact(ctx context.Context, customerID, contactID int64) error
+	// SendVerification has notify-service send a new code to an unverified
+	// contact, replacing any code sent before, and returns when it expires.
+	SendVerification(ctx context.Context, customerID, contactID int64) (time.Time, error)
+	// ConfirmVerification verifies the contact if code is the last one sent
+	// to it. The first contact of a type to be verified becomes primary.
+	ConfirmVerification(ctx context.Context, customerID, contactID int64, code string) (*Contact, error)
+}
+
+var _ Service = (*service)(nil)
+
+type service struct {
+	repo    Repository
+	pub     event.EventPublisher
+	clock   clock.Clock
+	newCode func() (string, error)
+	logger  *slog.Logger
+}
+
+// NewService publishes every change to a customer's contacts, and the
+// verification codes, to pub. clk decides when codes expire; nil means the
+// wall clock.
+func NewService(repo Repository, pub event.EventPublisher, clk clock.Clock, logger *slog.Logger) Service {
+	if repo == nil {
+		panic("contact repository cannot be nil")
+	}
+	if pub == nil {
+		panic("event publisher cannot be nil")
+	}
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+		logger.Warn("Warning: No logger provided to contact.NewService, using default stderr handler")
+	}
+	return &service{
+		repo:    repo,
+		pub:     pub,
+		clock:   clock.OrSystem(clk),
+		newCode: NewCode,
+		logger:  logger.With(slog.String("component", "contactService")),
+	}
+}
+
+func (s *service) ListContacts(ctx context.Context, customerID int64) ([]Contact, error) {
+	if customerID <= 0 {
+		return nil, fmt.Errorf("%w: customer ID must be a positive number", apperrors.ErrInvalidArgument)
+	}
+	contacts, err := s.repo.List(ctx, customerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list contacts of customer %d: %w", customerID, err)
+	}
+	return contacts, nil
+}
+
+func (s *service) AddContact(ctx context.Context, customerID int64, t Type, value string) (*Contact, error) {
+	if customerID <= 0 {
+		return nil, fmt.Errorf("%w: customer ID must be a positive number", apperrors.ErrInvalidArgument)
+	}
+	t = Type(strings.ToLower(strings.TrimSpace(string(t))))
+	value, err := Normalize(t, value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err)
+	}
+
+	c := &Contact{CustomerID: customerID, Type: t, Value: value}
+	if err := s.repo.Create(ctx, c); err != nil {
+		return nil, fmt.Errorf("failed to add contact for customer %d: %w", customerID, err)
+	}
+	s.logger.InfoContext(ctx, "Contact added", slog.Int64("customerID", customerID), slog.Int64("contactID", c.ID), slog.String("type", string(t)))
+	s.publishContacts(ctx, customerID)
+	return c, nil
+}
+
+func (s *service) UpdateContact(ctx context.Context, customerID, contactID int64, value string, primary bool) (*Contact, error) {
+	c, err := s.get(ctx, customerID, contactID)
+	if err != nil {
+		return nil, err
+	}
+	value, err = Normalize(c.Type, value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err)
+	}
+	if value != c.Value {
+		c.Value, c.Verified, c.VerifiedAt, c.Primary = value, false, nil, false
+	}
+	if primary && !c.Verified {
+		return nil, fmt.Errorf("%w: only a verified contact can be primary", apperrors.ErrConflict)
+	}
+	c.Primary = primary
+
+	if err := s.repo.Update(ctx, c); err != nil {
+		return nil, fmt.Errorf("failed to update contact %d of customer %d: %w", contactID, customerID, err)
+	}
+	s.logger.InfoContext(ctx, "Contact updated", slog.Int64("customerID", customerID), slog.Int64("contactID", contactID),
+		slog.Bool("verified", c.Verified), slog.Bool("primary", c.Primary))
+	s.publishContacts(ctx, customerID)
+	return c, nil
+}
+
+func (s *service) DeleteContact(ctx context.Context, customerID, contactID int64) error {
+	if customerID <= 0 || contactID <= 0 {
+		return fmt.Errorf("%w: customer and contact IDs must be positive numbers", apperrors.ErrInvalidArgument)
+	}
+	if err := s.repo.Delete(ctx, customerID, contactID); err != nil {
+		return fmt.Errorf("failed to delete contact %d of customer %d: %w", contactID, customerID, err)
+	}
+	s.logger.InfoContext(ctx, "Contact deleted", slog.Int64("customerID", customerID), slog.Int64("contactID", contactID))
+	s.publishContacts(ctx, customerID)
+	return nil
+}
+
+// SendVerification stores the code's hash before publishing the code. A code
+// that could not be published is dropped again, so that the customer can ask
+// for another one straight away.
+func (s *service) SendVerification(ctx context.Context, customerID, contactID int64) (time.Time, error) {
+	c, err := s.get(ctx, customerID, contactID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if c.Verified {
+		return time.Time{}, fmt.Errorf("%w: contact %d is already verified", apperrors.ErrConflict, contactID)
+	}
+	now := s.clock.Now()
+	pending, err := s.repo.GetVerification(ctx, contactID)
+	switch {
+	case err == nil && pending.Value == c.Value && now.Before(pending.SentAt.Add(ResendInterval)):
+		return time.Time{}, fmt.Errorf("%w: a code was sent to contact %d less than %s ago", apperrors.ErrConflict, contactID, ResendInterval)
+	case err != nil && !errors.Is(err, apperrors.ErrNotFound):
+		return time.Time{}, fmt.Errorf("failed to get verification of contact %d: %w", contactID, err)
+	}
+
+	code, err := s.newCode()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %v", apperrors.ErrInternalServer, err)
+	}
+	v := &Verification{ContactID: contactID, Value: c.Value, CodeHash: HashCode(code), ExpiresAt: now.Add(CodeTTL), SentAt: now}
+	if err := s.repo.SaveVerification(ctx, v); err != nil {
+		return time.Time{}, fmt.Errorf("failed to save verification of contact %d: %w", contactID, err)
+	}
+
+	requested := event.ContactVerificationMessage(event.CustomerContactVerificationRequestedEvent{
+		CustomerID: customerID,
+		ContactID:  contactID,
+		Type:       string(c.Type),
+		Value:      c.Value,
+		Code:       code,
+		ExpiresAt:  v.ExpiresAt,
+		Timestamp:  now,
+	})
+	if err := s.pub.PublishBatch(ctx, []event.Message{requested}); err != nil {
+		s.logger.ErrorContext(ctx, "Failed to publish verification code", slog.Int64("contactID", contactID), slog.Any("error", err))
+		if delErr := s.repo.DeleteVerification(context.WithoutCancel(ctx), contactID); delErr != nil {
+			s.logger.ErrorContext(ctx, "Failed to drop unsent verification code", slog.Int64("contactID", contactID), slog.Any("error", delErr))
+		}
+		return time.Time{}, fmt.Errorf("%w: the verification code could not be sent", apperrors.ErrUnavailable)
+	}
+	s.logger.InfoContext(ctx, "Verification code sent", slog.Int64("customerID", customerID), slog.Int64("contactID", contactID), slog.Time("expiresAt", v.ExpiresAt))
+	return v.ExpiresAt, nil
+}
+
+// ConfirmVerification cancels the code after MaxCodeAttempts wrong ones, so
+// that it cannot be guessed.
+func (s *service) ConfirmVerification(ctx context.Context, customerID, contactID int64, code string) (*Contact, error) {
+	if strings.TrimSpace(code) == "" {
+		return nil, fmt.Errorf("%w: code cannot be empty", apperrors.ErrInvalidArgument)
+	}
+	c, err := s.get(ctx, customerID, contactID)
+	if err != nil {
+		return nil, err
+	}
+	if c.Verified {
+		return nil, fmt.Errorf("%w: contact %d is already verified", apperrors.ErrConflict, contactID)
+	}
+	v, err := s.repo.GetVerification(ctx, contactID)
+	if errors.Is(err, apperrors.ErrNotFound) || (err == nil && v.Value != c.Value) {
+		return nil, fmt.Errorf("%w: no code is pending for contact %d, request one", apperrors.ErrConflict, contactID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get verification of contact %d: %w", contactID, err)
+	}
+	now := s.clock.Now()
+	if !now.Before(v.ExpiresAt) {
+		return nil, fmt.Errorf("%w: the code for contact %d expired, request a new one", apperrors.ErrConflict, contactID)
+	}
+
+	if !v.Matches(code) {
+		attempts, err := s.repo.CountFailedAttempt(ctx, contactID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count verification attempt of contact %d: %w", contactID, err)
+		}
+		if attempts < MaxCodeAttempts {
+			s.logger.WarnContext(ctx, "Wrong verification code", slog.Int64("contactID", contactID), slog.Int("attempts", attempts))
+			return nil, fmt.Errorf("%w: wrong verification code", apperrors.ErrInvalidArgument)
+		}
+		if err := s.repo.DeleteVerification(ctx, contactID); err != nil {
+			return nil, fmt.Errorf("failed to cancel verification of contact %d: %w", contactID, err)
+		}
+		s.logger.WarnContext(ctx, "Verification cancelled after too many wrong codes", slog.Int64("contactID", contactID))
+		return nil, fmt.Errorf("%w: too many wrong codes for contact %d, request a new one", apperrors.ErrConflict, contactID)
+	}
+
+	contacts, err := s.repo.List(ctx, customerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list contacts of customer %d: %w", customerID, err)
+	}
+	c.Verified, c.VerifiedAt, c.Primary = true, &now, true
+	for _, other := range contacts {
+		if other.Type == c.Type && other.Primary {
+			c.Primary = false
+		}
+	}
+	if err := s.repo.Update(ctx, c); err != nil {
+		return nil, fmt.Errorf("failed to verify contact %d of customer %d: %w", contactID, customerID, err)
+	}
+	if err := s.repo.DeleteVerification(ctx, contactID); err != nil {
+		// The contact is verified; the code left behind cannot be used again.
+		s.logger.WarnContext(ctx, "Failed to drop used verification code", slog.Int64("contactID", contactID), slog.Any("error", err))
+	}
+	s.logger.InfoContext(ctx, "Contact verified", slog.Int64("customerID", customerID), slog.Int64("contactID", contactID), slog.Bool("primary", c.Primary))
+	s.publishContacts(ctx, customerID)
+	return c, nil
+}
+
+func (s *service) get(ctx context.Context, customerID, contactID int64) (*Contact, error) {
+	if customerID <= 0 || contactID <= 0 {
+		return nil, fmt.Errorf("%w: customer and contact IDs must be positive numbers", apperrors.ErrInvalidArgument)
+	}
+	c, err := s.repo.Get(ctx, customerID, contactID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get contact %d of customer %d: %w", contactID, customerID, err)
+	}
+	return c, nil
+}
+
+// publishContacts sends the customer's contacts as they are now stored. A
+// change that could not be published is only logged, like the other
+// customer events: notify-service catches up with the next change or a
+// replay from the event log.
+func (s *service) publishContacts(ctx context.Context, customerID int64) {
+	contacts, err := s.repo.List(ctx, customerID)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Contacts saved, but FAILED to read them for the contacts event", slog.Int64("customerID", customerID), slog.Any("error", err))
+		return
+	}
+	now := s.clock.Now()
+	payload := make([]event.ContactPayload, 0, len(contacts))
+	for _, c := range contacts {
+		payload = append(payload, event.ContactPayload{ContactID: c.ID, Type: string(c.Type), Value: c.Value, Verified: c.Verified, Primary: c.Primary})
+	}
+	changed := event.ContactsChangedMessage(event.CustomerContactsChangedEvent{
+		CustomerID: customerID,
+		Contacts:   payload,
+		UpdatedAt:  now,
+		Timestamp:  now,
+	})
+	if err := s.pub.PublishBatch(ctx, []event.Message{changed}); err != nil {
+		s.logger.ErrorContext(ctx, "Contacts saved, but FAILED to publish contacts event", slog.Int64("customerID", customerID), slog.Any("error", err))
+	}
+}
@@ -0,0 +1,331 @@
+package contact
+
+import (
+	"billing-engine/internal/event"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+var testNow = time.Date(2025, 3, 5, 9, 0, 0, 0, time.UTC)
+
+// capturePublisher keeps the batches it is handed, or fails them with err.
+type capturePublisher struct {
+	messages []event.Message
+	err      error
+}
+
+func (p *capturePublisher) PublishCustomerDelinquencyChanged(context.Context, event.CustomerDelinquencyChangedEvent) error {
+	return nil
+}
+
+func (p *capturePublisher) PublishCustomerCreated(context.Context, event.CustomerCreatedEvent) error {
+	return nil
+}
+
+func (p *capturePublisher) PublishCustomerUpdated(context.Context, event.CustomerUpdatedEvent) error {
+	return nil
+}
+
+func (p *capturePublisher) PublishBatch(_ context.Context, messages []event.Message) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.messages = append(p.messages, messages...)
+	return nil
+}
+
+func newTestService(repo Repository, pub event.EventPublisher) *service {
+	svc := NewService(repo, pub, clock.NewFake(testNow), testLogger).(*service)
+	svc.newCode = func() (string, error) { return "482913", nil }
+	return svc
+}
+
+func TestServiceAddContact(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("stores the normalized contact and publishes the list", func(t *testing.T) {
+		repo, pub := new(MockRepository), &capturePublisher{}
+		svc := newTestService(repo, pub)
+		repo.On("Create", ctx, mock.MatchedBy(func(c *Contact) bool {
+			c.ID = 12
+			return c.CustomerID == 7 && c.Type == TypePhone && c.Value == "+6281234567890" && !c.Verified
+		})).Return(nil).Once()
+		repo.On("List", ctx, int64(7)).Return([]Contact{{ID: 12, CustomerID: 7, Type: TypePhone, Value: "+6281234567890"}}, nil).Once()
+
+		c, err := svc.AddContact(ctx, 7, "Phone", "+62 812-3456-7890")
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(12), c.ID)
+		require.Len(t, pub.messages, 1)
+		changed := pub.messages[0].Payload.(event.CustomerContactsChangedEvent)
+		assert.Equal(t, []event.ContactPayload{{ContactID: 12, Type: "phone", Value: "+6281234567890"}}, changed.Contacts)
+		assert.Equal(t, testNow, changed.UpdatedAt)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("rejects a value that cannot be sent to", func(t *testing.T) {
+		repo := new(MockRepository)
+		svc := newTestService(repo, &capturePublisher{})
+
+		_, err := svc.AddContact(ctx, 7, TypeEmail, "not an address")
+
+		assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+		repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("a failed contacts event keeps the contact", func(t *testing.T) {
+		repo := new(MockRepository)
+		svc := newTestService(repo, &capturePublisher{err: errors.New("broker down")})
+		repo.On("Create", ctx, mock.Anything).Return(nil).Once()
+		repo.On("List", ctx, int64(7)).Return([]Contact{}, nil).Once()
+
+		_, err := svc.AddContact(ctx, 7, TypeEmail, "ayu@example.com")
+
+		assert.NoError(t, err)
+	})
+}
+
+func TestServiceUpdateContact(t *testing.T) {
+	ctx := context.Background()
+	verifiedAt := testNow.Add(-time.Hour)
+
+	t.Run("a new value has to be verified again", func(t *testing.T) {
+		repo := new(MockRepository)
+		svc := newTestService(repo, &capturePublisher{})
+		repo.On("Get", ctx, int64(7), int64(12)).Return(&Contact{ID: 12, CustomerID: 7, Type: TypePhone, Value: "+6281234567890", Verified: true, Primary: true, VerifiedAt: &verifiedAt}, nil).Once()
+		repo.On("Update", ctx, mock.MatchedBy(func(c *Contact) bool {
+			return c.Value == "+6281111111111" && !c.Verified && c.VerifiedAt == nil && !c.Primary
+		})).Return(nil).Once()
+		repo.On("List", ctx, int64(7)).Return([]Contact{}, nil).Once()
+
+		c, err := svc.UpdateContact(ctx, 7, 12, "+6281111111111", false)
+
+		require.NoError(t, err)
+		assert.False(t, c.Verified)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("only a verified contact can be primary", func(t *testing.T) {
+		repo := new(MockRepository)
+		svc := newTestService(repo, &capturePublisher{})
+		repo.On("Get", ctx, int64(7), int64(12)).Return(&Contact{ID: 12, CustomerID: 7, Type: TypePhone, Value: "+6281234567890"}, nil).Once()
+
+		_, err := svc.UpdateContact(ctx, 7, 12, "+6281234567890", true)
+
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+		repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("unknown contact", func(t *testing.T) {
+		repo := new(MockRepository)
+		svc := newTestService(repo, &capturePublisher{})
+		repo.On("Get", ctx, int64(7), int64(99)).Return(nil, apperrors.ErrNotFound).Once()
+
+		_, err := svc.UpdateContact(ctx, 7, 99, "+6281234567890", false)
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+}
+
+func TestServiceSendVerification(t *testing.T) {
+	ctx := context.Background()
+	unverified := func() *Contact {
+		return &Contact{ID: 13, CustomerID: 7, Type: TypeEmail, Value: "ayu@example.com"}
+	}
+
+	t.Run("stores the hash and publishes the code privately", func(t *testing.T) {
+		repo, pub := new(MockRepository), &capturePublisher{}
+		svc := newTestService(repo, pub)
+		repo.On("Get", ctx, int64(7), int64(13)).Return(unverified(), nil).Once()
+		repo.On("GetVerification", ctx, int64(13)).Return(nil, apperrors.ErrNotFound).Once()
+		repo.On("SaveVerification", ctx, mock.MatchedBy(func(v *Verification) bool {
+			return v.ContactID == 13 && v.Value == "ayu@example.com" && v.CodeHash == HashCode("482913") && v.SentAt.Equal(testNow)
+		})).Return(nil).Once()
+
+		expiresAt, err := svc.SendVerification(ctx, 7, 13)
+
+		require.NoError(t, err)
+		assert.Equal(t, testNow.Add(CodeTTL), expiresAt)
+		require.Len(t, pub.messages, 1)
+		assert.True(t, pub.messages[0].Private)
+		requested := pub.messages[0].Payload.(event.CustomerContactVerificationRequestedEvent)
+		assert.Equal(t, "482913", requested.Code)
+		assert.Equal(t, "ayu@example.com", requested.Value)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("a code sent moments ago is not sent again", func(t *testing.T) {
+		repo := new(MockRepository)
+		svc := newTestService(repo, &capturePublisher{})
+		repo.On("Get", ctx, int64(7), int64(13)).Return(unverified(), nil).Once()
+		repo.On("GetVerification", ctx, int64(13)).Return(&Verification{ContactID: 13, Value: "ayu@example.com", SentAt: testNow.Add(-30 * time.Second)}, nil).Once()
+
+		_, err := svc.SendVerification(ctx, 7, 13)
+
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+		repo.AssertNotCalled(t, "SaveVerification", mock.Anything, mock.Anything)
+	})
+
+	t.Run("an unsent code is dropped", func(t *testing.T) {
+		repo := new(MockRepository)
+		svc := newTestService(repo, &capturePublisher{err: errors.New("broker down")})
+		repo.On("Get", ctx, int64(7), int64(13)).Return(unverified(), nil).Once()
+		repo.On("GetVerification", ctx, int64(13)).Return(nil, apperrors.ErrNotFound).Once()
+		repo.On("SaveVerification", ctx, mock.Anything).Return(nil).Once()
+		repo.On("DeleteVerification", mock.Anything, int64(13)).Return(nil).Once()
+
+		_, err := svc.SendVerification(ctx, 7, 13)
+
+		assert.ErrorIs(t, err, apperrors.ErrUnavailable)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("verified contact", func(t *testing.T) {
+		repo := new(MockRepository)
+		svc := newTestService(repo, &capturePublisher{})
+		c := unverified()
+		c.Verified = true
+		repo.On("Get", ctx, int64(7), int64(13)).Return(c, nil).Once()
+
+		_, err := svc.SendVerification(ctx, 7, 13)
+
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+	})
+}
+
+func TestServiceConfirmVerification(t *testing.T) {
+	ctx := context.Background()
+	unverified := func() *Contact {
+		return &Contact{ID: 13, CustomerID: 7, Type: TypeEmail, Value: "ayu@example.com"}
+	}
+	pending := func() *Verification {
+		return &Verification{ContactID: 13, Value: "ayu@example.com", CodeHash: HashCode("482913"), ExpiresAt: testNow.Add(time.Minute), SentAt: testNow.Add(-time.Minute)}
+	}
+
+	t.Run("verifies and makes the first of its type primary", func(t *testing.T) {
+		repo, pub := new(MockRepository), &capturePublisher{}
+		svc := newTestService(repo, pub)
+		repo.On("Get", ctx, int64(7), int64(13)).Return(unverified(), nil).Once()
+		repo.On("GetVerification", ctx, int64(13)).Return(pending(), nil).Once()
+		repo.On("List", ctx, int64(7)).Return([]Contact{{ID: 12, Type: TypePhone, Verified: true, Primary: true}, *unverified()}, nil)
+		repo.On("Update", ctx, mock.MatchedBy(func(c *Contact) bool {
+			return c.Verified && c.Primary && c.VerifiedAt.Equal(testNow)
+		})).Return(nil).Once()
+		repo.On("DeleteVerification", ctx, int64(13)).Return(nil).Once()
+
+		c, err := svc.ConfirmVerification(ctx, 7, 13, "482913")
+
+		require.NoError(t, err)
+		assert.True(t, c.Primary, "the phone's primary flag does not count for email")
+		require.Len(t, pub.messages, 1)
+		assert.Equal(t, event.TypeCustomerContactsChanged, pub.messages[0].Type)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("a second contact of the type is not primary", func(t *testing.T) {
+		repo := new(MockRepository)
+		svc := newTestService(repo, &capturePublisher{})
+		repo.On("Get", ctx, int64(7), int64(13)).Return(unverified(), nil).Once()
+		repo.On("GetVerification", ctx, int64(13)).Return(pending(), nil).Once()
+		repo.On("List", ctx, int64(7)).Return([]Contact{{ID: 11, Type: TypeEmail, Verified: true, Primary: true}}, nil)
+		repo.On("Update", ctx, mock.MatchedBy(func(c *Contact) bool { return c.Verified && !c.Primary })).Return(nil).Once()
+		repo.On("DeleteVerification", ctx, int64(13)).Return(nil).Once()
+
+		_, err := svc.ConfirmVerification(ctx, 7, 13, "482913")
+
+		require.NoError(t, err)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("wrong code counts an attempt", func(t *testing.T) {
+		repo := new(MockRepository)
+		svc := newTestService(repo, &capturePublisher{})
+		repo.On("Get", ctx, int64(7), int64(13)).Return(unverified(), nil).Once()
+		repo.On("GetVerification", ctx, int64(13)).Return(pending(), nil).Once()
+		repo.On("CountFailedAttempt", ctx, int64(13)).Return(2, nil).Once()
+
+		_, err := svc.ConfirmVerification(ctx, 7, 13, "111111")
+
+		assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+		repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("the last wrong code cancels the verification", func(t *testing.T) {
+		repo := new(MockRepository)
+		svc := newTestService(repo, &capturePublisher{})
+		repo.On("Get", ctx, int64(7), int64(13)).Return(unverified(), nil).Once()
+		repo.On("GetVerification", ctx, int64(13)).Return(pending(), nil).Once()
+		repo.On("CountFailedAttempt", ctx, int64(13)).Return(MaxCodeAttempts, nil).Once()
+		repo.On("DeleteVerification", ctx, int64(13)).Return(nil).Once()
+
+		_, err := svc.ConfirmVerification(ctx, 7, 13, "111111")
+
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("expired code", func(t *testing.T) {
+		repo := new(MockRepository)
+		svc := newTestService(repo, &capturePublisher{})
+		v := pending()
+		v.ExpiresAt = testNow
+		repo.On("Get", ctx, int64(7), int64(13)).Return(unverified(), nil).Once()
+		repo.On("GetVerification", ctx, int64(13)).Return(v, nil).Once()
+
+		_, err := svc.ConfirmVerification(ctx, 7, 13, "482913")
+
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+	})
+
+	t.Run("code sent to the contact's old value", func(t *testing.T) {
+		repo := new(MockRepository)
+		svc := newTestService(repo, &capturePublisher{})
+		v := pending()
+		v.Value = "old@example.com"
+		repo.On("Get", ctx, int64(7), int64(13)).Return(unverified(), nil).Once()
+		repo.On("GetVerification", ctx, int64(13)).Return(v, nil).Once()
+
+		_, err := svc.ConfirmVerification(ctx, 7, 13, "482913")
+
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+		repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("no code pending", func(t *testing.T) {
+		repo := new(MockRepository)
+		svc := newTestService(repo, &capturePublisher{})
+		repo.On("Get", ctx, int64(7), int64(13)).Return(unverified(), nil).Once()
+		repo.On("GetVerification", ctx, int64(13)).Return(nil, apperrors.ErrNotFound).Once()
+
+		_, err := svc.ConfirmVerification(ctx, 7, 13, "482913")
+
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+	})
+}
+
+func TestServiceDeleteContact(t *testing.T) {
+	ctx := context.Background()
+	repo, pub := new(MockRepository), &capturePublisher{}
+	svc := newTestService(repo, pub)
+	repo.On("Delete", ctx, int64(7), int64(12)).Return(nil).Once()
+	repo.On("List", ctx, int64(7)).Return(nil, nil).Once()
+
+	require.NoError(t, svc.DeleteContact(ctx, 7, 12))
+
+	require.Len(t, pub.messages, 1)
+	changed := pub.messages[0].Payload.(event.CustomerContactsChangedEvent)
+	assert.NotNil(t, changed.Contacts, "an empty list is published as []")
+	assert.Empty(t, changed.Contacts)
+}
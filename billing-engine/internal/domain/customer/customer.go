@@ -1,16 +1,31 @@
 package customer
 
-import "time"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type Customer struct {
 	CustomerID   int64     `json:"customerId"`
+	PublicID     uuid.UUID `json:"publicId"`
 	Name         string    `json:"name"`
 	Address      string    `json:"address"`
 	IsDelinquent bool      `json:"isDelinquent"`
 	Active       bool      `json:"active"`
 	LoanID       *int64    `json:"loanId,omitempty"`
-	CreateDate   time.Time `json:"createDate"`
-	UpdatedAt    time.Time `json:"updatedAt"`
+	ExternalRef  *string   `json:"externalRef,omitempty"`
+	// RiskScore, RiskGrade and RiskScoredAt hold the customer's latest
+	// credit bureau assessment and are nil until the first one is stored.
+	RiskScore    *int       `json:"riskScore,omitempty"`
+	RiskGrade    *string    `json:"riskGrade,omitempty"`
+	RiskScoredAt *time.Time `json:"riskScoredAt,omitempty"`
+	// MergedIntoID is the customer this one was merged into as a duplicate,
+	// at MergedAt. Both are nil for a customer that was never merged.
+	MergedIntoID *int64     `json:"mergedIntoId,omitempty"`
+	MergedAt     *time.Time `json:"mergedAt,omitempty"`
+	CreateDate   time.Time  `json:"createDate"`
+	UpdatedAt    time.Time  `json:"updatedAt"`
 }
 
 func NewCustomer(name, address string) *Customer {
@@ -2,6 +2,7 @@ package customer_test
 
 import (
 	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/pkg/apperrors"
 	"testing"
 	"time"
 
@@ -131,3 +132,60 @@ func TestCustomerDeactivate(t *testing.T) {
 		assert.Equal(t, initialUpdateTime, cust.UpdatedAt, "UpdatedAt should NOT be updated")
 	})
 }
+
+func TestCheckMerge(t *testing.T) {
+	loanA, loanB, mergedInto := int64(5), int64(6), int64(30)
+	active := func(id int64, loanID *int64) *customer.Customer {
+		return &customer.Customer{CustomerID: id, Active: true, LoanID: loanID}
+	}
+
+	assert.NoError(t, customer.CheckMerge(active(1, nil), active(2, &loanA)), "the target takes the source's loan")
+	assert.NoError(t, customer.CheckMerge(active(1, &loanA), active(2, nil)))
+
+	assert.ErrorIs(t, customer.CheckMerge(active(1, nil), active(1, nil)), apperrors.ErrInvalidArgument)
+	assert.ErrorIs(t, customer.CheckMerge(active(1, &loanA), active(2, &loanB)), apperrors.ErrConflict)
+	assert.ErrorIs(t, customer.CheckMerge(&customer.Customer{CustomerID: 1}, active(2, nil)), apperrors.ErrConflict, "an inactive target")
+
+	source := active(2, nil)
+	source.Active, source.MergedIntoID = false, &mergedInto
+	err := customer.CheckMerge(active(1, nil), source)
+	assert.ErrorIs(t, err, customer.ErrAlreadyMerged)
+	assert.ErrorIs(t, err, apperrors.ErrConflict)
+	assert.ErrorIs(t, customer.CheckMerge(source, active(1, nil)), customer.ErrAlreadyMerged)
+}
+
+func TestNewMerge(t *testing.T) {
+	now := time.Date(2025, 5, 2, 10, 0, 0, 0, time.UTC)
+	loanID := int64(5)
+	target := &customer.Customer{CustomerID: 1, Active: true}
+	source := &customer.Customer{CustomerID: 2, Active: true, IsDelinquent: true, LoanID: &loanID}
+
+	m := customer.NewMerge(target, source, now)
+
+	assert.Equal(t, &loanID, m.LoanID)
+	assert.Equal(t, &loanID, target.LoanID)
+	assert.True(t, target.IsDelinquent, "the delinquent loan moves with its flag")
+	assert.Equal(t, now, target.UpdatedAt)
+	assert.Nil(t, source.LoanID)
+	assert.False(t, source.Active)
+	assert.Equal(t, int64(1), *source.MergedIntoID)
+	assert.Equal(t, now, *source.MergedAt)
+}
+
+func TestNewDuplicatePolicy(t *testing.T) {
+	for input, want := range map[string]customer.DuplicateCheck{"": "", "off": "", "exact": customer.DuplicateCheckExact, " Fuzzy ": customer.DuplicateCheckFuzzy} {
+		p, err := customer.NewDuplicatePolicy(input)
+		assert.NoError(t, err, input)
+		assert.Equal(t, want, p.Check, input)
+	}
+
+	_, err := customer.NewDuplicatePolicy("soundex")
+	assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+}
+
+func TestMatchKey(t *testing.T) {
+	assert.Equal(t, "jl merdeka 5 bandung", customer.MatchKey("Jalan Merdeka No. 5, Bandung"))
+	assert.Equal(t, "jl merdeka 5 bandung", customer.MatchKey("  jl. merdeka   nomor 5 - BANDUNG "))
+	assert.Equal(t, "1 main st", customer.MatchKey("1 Main Street"))
+	assert.Empty(t, customer.MatchKey(" - "))
+}
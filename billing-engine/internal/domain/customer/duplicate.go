@@ -0,0 +1,139 @@
+package customer
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type DuplicateCheck string
+
+const (
+	DuplicateCheckOff DuplicateCheck = "off"
+	// DuplicateCheckExact matches customers with the same name and address,
+	// as typed apart from surrounding spaces.
+	DuplicateCheckExact DuplicateCheck = "exact"
+	// DuplicateCheckFuzzy compares the words of names in any order and the
+	// MatchKey of addresses instead, so case, punctuation, spacing and common
+	// street abbreviations do not tell two customers apart.
+	DuplicateCheckFuzzy DuplicateCheck = "fuzzy"
+
+	// MaxDuplicateCandidates caps the matches a refused creation lists.
+	MaxDuplicateCandidates = 10
+
+	// duplicateScanLimit caps the customers whose name contains the probe
+	// that one check compares. It bounds the work of a very common name.
+	duplicateScanLimit = 500
+)
+
+// addressAbbreviations spell street words the short way, and drop the word
+// for "number" that may or may not precede a house number. Only addresses
+// are rewritten; a person's name keeps its words.
+var addressAbbreviations = map[string]string{
+	"jalan":     "jl",
+	"gang":      "gg",
+	"street":    "st",
+	"road":      "rd",
+	"avenue":    "ave",
+	"boulevard": "blvd",
+	"nomor":     "",
+	"number":    "",
+	"no":        "",
+}
+
+// DuplicatePolicy decides when a new customer repeats an existing active
+// one. The zero policy checks nothing.
+type DuplicatePolicy struct {
+	Check DuplicateCheck
+}
+
+// NewDuplicatePolicy parses the configured check; empty means off.
+func NewDuplicatePolicy(check string) (DuplicatePolicy, error) {
+	switch c := DuplicateCheck(strings.ToLower(strings.TrimSpace(check))); c {
+	case "", DuplicateCheckOff:
+		return DuplicatePolicy{}, nil
+	case DuplicateCheckExact, DuplicateCheckFuzzy:
+		return DuplicatePolicy{Check: c}, nil
+	default:
+		return DuplicatePolicy{}, fmt.Errorf("%w: unknown duplicate check %q, want %s, %s or %s", apperrors.ErrInvalidArgument, check, DuplicateCheckOff, DuplicateCheckExact, DuplicateCheckFuzzy)
+	}
+}
+
+func (p DuplicatePolicy) enabled() bool {
+	return p.Check == DuplicateCheckExact || p.Check == DuplicateCheckFuzzy
+}
+
+// probe is a part of name that every match's name contains, ignoring case,
+// for the repository to narrow the customers down before matches compares
+// them. For a fuzzy check it is the longest word of the name.
+func (p DuplicatePolicy) probe(name string) string {
+	if p.Check != DuplicateCheckFuzzy {
+		return strings.ToLower(name)
+	}
+	longest := ""
+	for _, word := range matchWords(name) {
+		if len(word) > len(longest) {
+			longest = word
+		}
+	}
+	return longest
+}
+
+// matches reports whether c repeats a new customer with name and address,
+// both already trimmed.
+func (p DuplicatePolicy) matches(c *Customer, name, address string) bool {
+	switch p.Check {
+	case DuplicateCheckExact:
+		return strings.TrimSpace(c.Name) == name && strings.TrimSpace(c.Address) == address
+	case DuplicateCheckFuzzy:
+		return nameKey(c.Name) == nameKey(name) && MatchKey(c.Address) == MatchKey(address)
+	default:
+		return false
+	}
+}
+
+// MatchKey reduces an address to what a fuzzy duplicate check compares: its
+// letters and digits in lower case, one space between words, with street
+// words abbreviated, so "Jalan Merdeka No. 5" and "jl merdeka 5" match.
+func MatchKey(s string) string {
+	words := matchWords(s)
+	kept := words[:0]
+	for _, word := range words {
+		if short, ok := addressAbbreviations[word]; ok {
+			word = short
+		}
+		if word != "" {
+			kept = append(kept, word)
+		}
+	}
+	return strings.Join(kept, " ")
+}
+
+// nameKey is the words of a name in lower case and sorted, so "Santoso,
+// Budi" and "budi santoso" match.
+func nameKey(name string) string {
+	words := matchWords(name)
+	slices.Sort(words)
+	return strings.Join(words, " ")
+}
+
+func matchWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// duplicateError refuses a creation that repeats the customers ids, listed
+// in the error's candidateIds metadata for the client to pick from or to
+// retry with force.
+func duplicateError(ids []int64) error {
+	list := make([]string, len(ids))
+	for i, id := range ids {
+		list[i] = strconv.FormatInt(id, 10)
+	}
+	return apperrors.New(apperrors.CodeConflict, "a customer with this name and address already exists").
+		WithMeta("candidateIds", strings.Join(list, ","))
+}
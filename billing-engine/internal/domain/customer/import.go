@@ -0,0 +1,239 @@
+package customer
+
+import (
+	"billing-engine/internal/event"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	ImportStatusCreated   = "created"
+	ImportStatusDuplicate = "duplicate"
+	ImportStatusInvalid   = "invalid"
+	ImportStatusFailed    = "failed"
+
+	DefaultImportChunkSize = 500
+
+	// MaxExternalRefLength matches the external_ref columns on customers and
+	// loans.
+	MaxExternalRefLength = 64
+
+	maxNameLength = 255
+)
+
+// ImportRow is one customer record read from an upload. Line is the 1-based
+// position in the source file and is echoed back in the report.
+type ImportRow struct {
+	Line        int
+	Name        string
+	Address     string
+	ExternalRef string
+}
+
+type ImportResult struct {
+	Line        int
+	ExternalRef string
+	Status      string
+	CustomerID  int64
+	Error       string
+}
+
+type ImportReport struct {
+	Total      int
+	Created    int
+	Duplicates int
+	Invalid    int
+	Failed     int
+	Results    []ImportResult
+}
+
+type ImportRepository interface {
+	// InsertImportBatch inserts rows in one round trip and returns the new
+	// customer ID for each row, in order. A zero ID means a customer with the
+	// same external reference already exists and the row was skipped.
+	InsertImportBatch(ctx context.Context, rows []ImportRow) ([]int64, error)
+}
+
+type ImportService interface {
+	// ImportCustomers reports through progress, when not nil, how many rows
+	// have an outcome so far.
+	ImportCustomers(ctx context.Context, rows []ImportRow, progress func(processed int)) *ImportReport
+}
+
+var _ ImportService = (*importService)(nil)
+
+type importService struct {
+	repo      ImportRepository
+	events    *event.Buffer
+	chunkSize int
+	clock     clock.Clock
+	logger    *slog.Logger
+}
+
+// NewImportService builds the import. Every created customer raises a
+// customer.created event through events, which batches them; a nil buffer
+// publishes none. clk stamps the events; nil means the wall clock.
+func NewImportService(repo ImportRepository, events *event.Buffer, chunkSize int, clk clock.Clock, logger *slog.Logger) ImportService {
+	if repo == nil {
+		panic("customer import repository cannot be nil")
+	}
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+		logger.Warn("Warning: No logger provided to NewImportService, using default stderr handler")
+	}
+	if chunkSize <= 0 {
+		chunkSize = DefaultImportChunkSize
+	}
+	return &importService{
+		repo:      repo,
+		events:    events,
+		chunkSize: chunkSize,
+		clock:     clock.OrSystem(clk),
+		logger:    logger.With(slog.String("component", "customerImportService")),
+	}
+}
+
+// ImportCustomers validates and deduplicates rows, then inserts the remainder
+// in chunks. A failing chunk marks only its own rows as failed; chunks that
+// were already inserted stay committed.
+func (s *importService) ImportCustomers(ctx context.Context, rows []ImportRow, progress func(processed int)) *ImportReport {
+	if progress == nil {
+		progress = func(int) {}
+	}
+	s.logger.InfoContext(ctx, "Starting customer import", slog.Int("rows", len(rows)))
+
+	report := &ImportReport{Total: len(rows), Results: make([]ImportResult, len(rows))}
+	seen := make(map[string]int, len(rows))
+	pending := make([]int, 0, len(rows))
+	cleaned := make([]ImportRow, len(rows))
+
+	for i, row := range rows {
+		row.Name = strings.TrimSpace(row.Name)
+		row.Address = strings.TrimSpace(row.Address)
+		row.ExternalRef = strings.TrimSpace(row.ExternalRef)
+		cleaned[i] = row
+
+		result := &report.Results[i]
+		result.Line = row.Line
+		result.ExternalRef = row.ExternalRef
+
+		if err := validateImportRow(row); err != nil {
+			result.Status = ImportStatusInvalid
+			result.Error = err.Error()
+			continue
+		}
+		if firstLine, ok := seen[row.ExternalRef]; ok {
+			result.Status = ImportStatusDuplicate
+			result.Error = fmt.Sprintf("externalRef already used on line %d", firstLine)
+			continue
+		}
+		seen[row.ExternalRef] = row.Line
+		pending = append(pending, i)
+	}
+
+	// Rejected rows are settled before anything is inserted.
+	processed := len(rows) - len(pending)
+	progress(processed)
+	for start := 0; start < len(pending); start += s.chunkSize {
+		end := min(start+s.chunkSize, len(pending))
+		s.insertChunk(ctx, cleaned, pending[start:end], report)
+		processed += end - start
+		progress(processed)
+	}
+
+	for _, result := range report.Results {
+		switch result.Status {
+		case ImportStatusCreated:
+			report.Created++
+		case ImportStatusDuplicate:
+			report.Duplicates++
+		case ImportStatusInvalid:
+			report.Invalid++
+		case ImportStatusFailed:
+			report.Failed++
+		}
+	}
+
+	s.logger.InfoContext(ctx, "Finished customer import",
+		slog.Int("created", report.Created),
+		slog.Int("duplicates", report.Duplicates),
+		slog.Int("invalid", report.Invalid),
+		slog.Int("failed", report.Failed))
+	return report
+}
+
+func (s *importService) insertChunk(ctx context.Context, rows []ImportRow, indexes []int, report *ImportReport) {
+	chunk := make([]ImportRow, len(indexes))
+	for i, idx := range indexes {
+		chunk[i] = rows[idx]
+	}
+
+	ids, err := s.repo.InsertImportBatch(ctx, chunk)
+	if err == nil && len(ids) != len(chunk) {
+		err = fmt.Errorf("repository returned %d IDs for %d rows", len(ids), len(chunk))
+	}
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed to insert import chunk",
+			slog.Int("firstLine", chunk[0].Line), slog.Int("rows", len(chunk)), slog.Any("error", err))
+		for _, idx := range indexes {
+			report.Results[idx].Status = ImportStatusFailed
+			report.Results[idx].Error = "could not store row, retry the import"
+		}
+		return
+	}
+
+	now := s.clock.Now()
+	var created []event.Message
+	for i, idx := range indexes {
+		result := &report.Results[idx]
+		if ids[i] == 0 {
+			result.Status = ImportStatusDuplicate
+			result.Error = "a customer with this externalRef already exists"
+			continue
+		}
+		result.Status = ImportStatusCreated
+		result.CustomerID = ids[i]
+		created = append(created, event.CustomerCreatedMessage(event.CustomerCreatedEvent{
+			Timestamp: now,
+			Payload: event.CustomerEventPayload{
+				CustomerID: ids[i],
+				Name:       chunk[i].Name,
+				Address:    chunk[i].Address,
+				Active:     true,
+				CreateDate: now,
+				UpdatedAt:  now,
+			},
+		}), event.RiskScoreRequestedMessage(event.CustomerRiskScoreRequestedEvent{
+			CustomerID:  ids[i],
+			ExternalRef: &chunk[i].ExternalRef,
+			Name:        chunk[i].Name,
+			Address:     chunk[i].Address,
+			Reason:      string(RiskScoreCustomerCreated),
+			Timestamp:   now,
+		}))
+	}
+	if s.events != nil && len(created) > 0 {
+		s.events.Add(ctx, created...)
+	}
+}
+
+func validateImportRow(row ImportRow) error {
+	switch {
+	case row.Name == "":
+		return fmt.Errorf("name cannot be empty")
+	case utf8.RuneCountInString(row.Name) > maxNameLength:
+		return fmt.Errorf("name cannot exceed %d characters", maxNameLength)
+	case row.Address == "":
+		return fmt.Errorf("address cannot be empty")
+	case row.ExternalRef == "":
+		return fmt.Errorf("externalRef cannot be empty")
+	case utf8.RuneCountInString(row.ExternalRef) > MaxExternalRefLength:
+		return fmt.Errorf("externalRef cannot exceed %d characters", MaxExternalRefLength)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package customer_test
+
+import (
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/event"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockImportRepository struct {
+	mock.Mock
+}
+
+func (m *MockImportRepository) InsertImportBatch(ctx context.Context, rows []customer.ImportRow) ([]int64, error) {
+	args := m.Called(ctx, rows)
+	ids, _ := args.Get(0).([]int64)
+	return ids, args.Error(1)
+}
+
+func newImportService(repo customer.ImportRepository, chunkSize int) customer.ImportService {
+	return customer.NewImportService(repo, nil, chunkSize, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestImportCustomersValidatesAndDeduplicates(t *testing.T) {
+	ctx := context.Background()
+	repo := new(MockImportRepository)
+	repo.On("InsertImportBatch", ctx, []customer.ImportRow{
+		{Line: 2, Name: "Jane", Address: "1 Main St", ExternalRef: "crm-1"},
+		{Line: 4, Name: "Bob", Address: "2 Main St", ExternalRef: "crm-2"},
+	}).Return([]int64{10, 0}, nil)
+
+	report := newImportService(repo, 10).ImportCustomers(ctx, []customer.ImportRow{
+		{Line: 2, Name: " Jane ", Address: "1 Main St", ExternalRef: " crm-1 "},
+		{Line: 3, Name: "", Address: "1 Main St", ExternalRef: "crm-3"},
+		{Line: 4, Name: "Bob", Address: "2 Main St", ExternalRef: "crm-2"},
+		{Line: 5, Name: "Jane again", Address: "1 Main St", ExternalRef: "crm-1"},
+	}, nil)
+
+	repo.AssertExpectations(t)
+	require.Len(t, report.Results, 4)
+	assert.Equal(t, 4, report.Total)
+	assert.Equal(t, 1, report.Created)
+	assert.Equal(t, 2, report.Duplicates)
+	assert.Equal(t, 1, report.Invalid)
+
+	assert.Equal(t, customer.ImportStatusCreated, report.Results[0].Status)
+	assert.Equal(t, int64(10), report.Results[0].CustomerID)
+	assert.Equal(t, "crm-1", report.Results[0].ExternalRef)
+	assert.Equal(t, customer.ImportStatusInvalid, report.Results[1].Status)
+	assert.Equal(t, "name cannot be empty", report.Results[1].Error)
+	assert.Equal(t, customer.ImportStatusDuplicate, report.Results[2].Status)
+	assert.Equal(t, customer.ImportStatusDuplicate, report.Results[3].Status)
+	assert.Contains(t, report.Results[3].Error, "line 2")
+}
+
+func TestImportCustomersChunksAndIsolatesFailures(t *testing.T) {
+	ctx := context.Background()
+	repo := new(MockImportRepository)
+	repo.On("InsertImportBatch", ctx, mock.MatchedBy(func(rows []customer.ImportRow) bool { return rows[0].Line == 1 })).
+		Return([]int64{1, 2}, nil).Once()
+	repo.On("InsertImportBatch", ctx, mock.MatchedBy(func(rows []customer.ImportRow) bool { return rows[0].Line == 3 })).
+		Return(nil, errors.New("connection reset")).Once()
+
+	rows := []customer.ImportRow{
+		{Line: 1, Name: "A", Address: "a", ExternalRef: "r1"},
+		{Line: 2, Name: "B", Address: "b", ExternalRef: "r2"},
+		{Line: 3, Name: "C", Address: "c", ExternalRef: "r3"},
+	}
+	var progress []int
+	report := newImportService(repo, 2).ImportCustomers(ctx, rows, func(processed int) { progress = append(progress, processed) })
+
+	repo.AssertExpectations(t)
+	assert.Equal(t, []int{0, 2, 3}, progress, "progress is reported after every chunk")
+	assert.Equal(t, 2, report.Created)
+	assert.Equal(t, 1, report.Failed)
+	assert.Equal(t, customer.ImportStatusFailed, report.Results[2].Status)
+	assert.NotContains(t, report.Results[2].Error, "connection reset")
+}
+
+func TestImportCustomersRejectsLongExternalRef(t *testing.T) {
+	repo := new(MockImportRepository)
+
+	report := newImportService(repo, 0).ImportCustomers(context.Background(), []customer.ImportRow{
+		{Line: 1, Name: "A", Address: "a", ExternalRef: strings.Repeat("x", 65)},
+	}, nil)
+
+	repo.AssertNotCalled(t, "InsertImportBatch", mock.Anything, mock.Anything)
+	assert.Equal(t, 1, report.Invalid)
+	assert.Equal(t, "externalRef cannot exceed 64 characters", report.Results[0].Error)
+}
+
+func TestImportCustomersPublishesCreatedCustomers(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	importedAt := time.Date(2025, 4, 2, 9, 0, 0, 0, time.UTC)
+	repo := new(MockImportRepository)
+	repo.On("InsertImportBatch", ctx, mock.Anything).Return([]int64{10, 0}, nil)
+	pub := new(MockEventPublisher)
+	pub.On("PublishBatch", ctx, mock.MatchedBy(func(messages []event.Message) bool {
+		if len(messages) != 2 {
+			return false
+		}
+		created := messages[0].Payload.(event.CustomerCreatedEvent)
+		requested := messages[1].Payload.(event.CustomerRiskScoreRequestedEvent)
+		return messages[0].Type == event.TypeCustomerCreated && created.Payload.CustomerID == 10 &&
+			created.Payload.Name == "Jane" && created.Payload.Active && created.Timestamp.Equal(importedAt) &&
+			messages[1].Type == event.TypeCustomerRiskScoreRequested && requested.CustomerID == 10 &&
+			*requested.ExternalRef == "crm-1" && requested.Reason == string(customer.RiskScoreCustomerCreated)
+	})).Return(nil).Once()
+	buffer := event.NewBuffer(pub, 10, time.Hour, logger)
+
+	report := customer.NewImportService(repo, buffer, 10, clock.NewFake(importedAt), logger).ImportCustomers(ctx, []customer.ImportRow{
+		{Line: 1, Name: "Jane", Address: "1 Main St", ExternalRef: "crm-1"},
+		{Line: 2, Name: "Bob", Address: "2 Main St", ExternalRef: "crm-2"},
+	}, nil)
+	assert.Equal(t, 1, report.Created)
+	pub.AssertNotCalled(t, "PublishBatch", mock.Anything, mock.Anything)
+
+	assert.NoError(t, buffer.Flush(ctx))
+	pub.AssertExpectations(t)
+}
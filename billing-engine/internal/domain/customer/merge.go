@@ -0,0 +1,77 @@
+package customer
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"fmt"
+	"time"
+)
+
+// ErrAlreadyMerged is returned for a merge into or out of a customer that
+// was itself merged away.
+var ErrAlreadyMerged = apperrors.New(apperrors.CodeConflict, "customer has already been merged into another customer")
+
+// Merge is a duplicate customer, Source, merged into Target, both as stored
+// after the merge. LoanID is the loan that moved to the target, nil when the
+// source held none; the loan's schedule and payments go with it. Moved counts
+// the other records that now point at the target.
+type Merge struct {
+	Target *Customer
+	Source *Customer
+	LoanID *int64
+	Moved  MergedRecords
+}
+
+// MergedRecords counts, per kind, the source's records a merge re-linked to
+// the target. ArchivedLoans are loans the source completed before the merge;
+// a restore links them to the target.
+type MergedRecords struct {
+	Notes                 int64
+	Attachments           int64
+	Documents             int64
+	Mandates              int64
+	CollectionAssignments int64
+	ArchivedLoans         int64
+	Events                int64
+}
+
+// CheckMerge reports whether source can be merged into target, both as
+// locked for the merge. A customer holds at most one loan, so two customers
+// with a loan each cannot be merged, and a deactivated customer cannot take
+// on the source's records.
+func CheckMerge(target, source *Customer) error {
+	if target.CustomerID == source.CustomerID {
+		return fmt.Errorf("%w: a customer cannot be merged into itself", apperrors.ErrInvalidArgument)
+	}
+	for _, c := range []*Customer{target, source} {
+		if c.MergedIntoID != nil {
+			return fmt.Errorf("%w: customer %d was merged into customer %d", ErrAlreadyMerged, c.CustomerID, *c.MergedIntoID)
+		}
+	}
+	if !target.Active {
+		return fmt.Errorf("%w: cannot merge into inactive customer %d", apperrors.ErrConflict, target.CustomerID)
+	}
+	if target.LoanID != nil && source.LoanID != nil {
+		return fmt.Errorf("%w: customers %d and %d both hold a loan (%d and %d)", apperrors.ErrConflict,
+			target.CustomerID, source.CustomerID, *target.LoanID, *source.LoanID)
+	}
+	return nil
+}
+
+// NewMerge is the merge of source into target at now, which CheckMerge
+// allowed. It updates both customers to what the merge stores: the target
+// takes the source's loan and, with it, its delinquency, and the source is
+// left inactive and without a loan.
+func NewMerge(target, source *Customer, now time.Time) *Merge {
+	m := &Merge{Target: target, Source: source, LoanID: source.LoanID}
+	if target.LoanID == nil {
+		target.LoanID = source.LoanID
+	}
+	target.IsDelinquent = target.IsDelinquent || source.IsDelinquent
+	target.UpdatedAt = now
+	source.LoanID = nil
+	source.Active = false
+	source.MergedIntoID = &target.CustomerID
+	source.MergedAt = &now
+	source.UpdatedAt = now
+	return m
+}
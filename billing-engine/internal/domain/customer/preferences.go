@@ -0,0 +1,55 @@
+package customer
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	ChannelSMS   = "sms"
+	ChannelEmail = "email"
+)
+
+// languageTag accepts a BCP 47 primary language with an optional region or
+// script, such as "id", "en-US" or "zh-Hant".
+var languageTag = regexp.MustCompile(`^[a-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// Preferences are a customer's communication preferences. PreferredChannel
+// and Language are empty when the customer has not chosen one, and
+// notify-service then uses its own defaults. Transactional messages are the
+// ones about the customer's loans, such as receipts and overdue notices;
+// marketing messages are everything else. A customer without stored
+// preferences has the zero value with UpdatedAt unset.
+type Preferences struct {
+	CustomerID          int64
+	PreferredChannel    string
+	Language            string
+	MarketingOptOut     bool
+	TransactionalOptOut bool
+	UpdatedAt           time.Time
+}
+
+// normalize trims and lower-cases the channel and the language's primary
+// subtag, then rejects values notify-service could not act on.
+func (p *Preferences) normalize() error {
+	p.PreferredChannel = strings.ToLower(strings.TrimSpace(p.PreferredChannel))
+	switch p.PreferredChannel {
+	case "", ChannelSMS, ChannelEmail:
+	default:
+		return fmt.Errorf("%w: preferred channel must be %q or %q", apperrors.ErrInvalidArgument, ChannelSMS, ChannelEmail)
+	}
+
+	p.Language = strings.TrimSpace(p.Language)
+	if primary, rest, found := strings.Cut(p.Language, "-"); found {
+		p.Language = strings.ToLower(primary) + "-" + rest
+	} else {
+		p.Language = strings.ToLower(p.Language)
+	}
+	if p.Language != "" && !languageTag.MatchString(p.Language) {
+		return fmt.Errorf("%w: language must be a language tag such as \"id\" or \"en-US\"", apperrors.ErrInvalidArgument)
+	}
+	return nil
+}
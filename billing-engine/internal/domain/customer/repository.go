@@ -1,18 +1,21 @@
 package customer
 
 import (
+	"billing-engine/internal/pkg/apperrors"
 	"context"
 	"errors"
+
+	"github.com/google/uuid"
 )
 
 var (
-	ErrNotFound = errors.New("customer not found")
+	ErrNotFound = apperrors.New(apperrors.CodeNotFound, "customer not found")
 
-	ErrDuplicateLoanID = errors.New("loan ID already assigned to another customer")
+	ErrDuplicateLoanID = apperrors.New(apperrors.CodeConflict, "loan ID already assigned to another customer")
 
-	ErrUpdateConflict = errors.New("update conflict detected")
+	ErrUpdateConflict = apperrors.New(apperrors.CodeConflict, "update conflict detected")
 
-	ErrCannotDeactivateActiveLoan = errors.New("cannot deactivate customer with active loan")
+	ErrCannotDeactivateActiveLoan = apperrors.New(apperrors.CodeFailedPrecondition, "cannot deactivate customer with active loan")
 
 	ErrCustomerAlreadyHasLoan = errors.New("customer already has an assigned active loan")
 )
@@ -22,13 +25,94 @@ type CustomerRepository interface {
 
 	FindByID(ctx context.Context, customerID int64) (*Customer, error)
 
+	FindByPublicID(ctx context.Context, publicID uuid.UUID) (*Customer, error)
+
 	FindByLoanID(ctx context.Context, loanID int64) (*Customer, error)
 
-	FindAll(ctx context.Context, activeOnly bool) ([]*Customer, error)
+	FindByExternalRef(ctx context.Context, externalRef string) (*Customer, error)
+
+	// FindByNameContaining returns up to limit active customers whose name
+	// contains part, ignoring case, by ID. A part without characters
+	// matches every name.
+	FindByNameContaining(ctx context.Context, part string, limit int) ([]*Customer, error)
+
+	// FindAll returns the customers matching filter. A sort field the
+	// repository does not know is ErrInvalidArgument.
+	FindAll(ctx context.Context, filter ListFilter) ([]*Customer, error)
 
 	Delete(ctx context.Context, customerID int64) error
 
 	SetDelinquencyStatus(ctx context.Context, customerID int64, isDelinquent bool) error
 
+	// SetDelinquencyStatusBulk applies all updates in one statement and
+	// returns the customers whose flag changed, as stored. Customers that do
+	// not exist or already have the requested flag are skipped.
+	SetDelinquencyStatusBulk(ctx context.Context, updates []CustomerDelinquency) ([]*Customer, error)
+
+	// MarkDelinquencyNotified records in one statement that each notice's
+	// customer was notified for its bucket, and returns the IDs of the
+	// customers that had been notified for another bucket or not at all.
+	// The others, and customers that do not exist, are skipped.
+	MarkDelinquencyNotified(ctx context.Context, notices []DelinquencyNotice) ([]int64, error)
+
+	// ClearDelinquencyNotices forgets the bucket the customers were last
+	// notified for, so that their next delinquency is notified again. The
+	// time of the last notice is kept.
+	ClearDelinquencyNotices(ctx context.Context, customerIDs []int64) error
+
 	SetActiveStatus(ctx context.Context, customerID int64, isActive bool) error
+
+	// SetRiskScore stores the assessment on the customer, replacing the
+	// previous one. An unknown customer is ErrNotFound.
+	SetRiskScore(ctx context.Context, a *RiskAssessment) error
+
+	// MergeCustomers merges the duplicate customer sourceID into targetID in
+	// one transaction, with both rows locked while CheckMerge and the mandate
+	// check run. The source's loan, notes, attachments, mandates, collection
+	// assignments, archived loans and recorded events move to the target,
+	// and the source is deactivated and points at the target. An unknown
+	// customer is ErrNotFound; a refused merge is a conflict.
+	MergeCustomers(ctx context.Context, targetID, sourceID int64) (*Merge, error)
+
+	// GetPreferences returns the customer's communication preferences, the
+	// zero Preferences for the customer when none are stored. An unknown
+	// customer is ErrNotFound.
+	GetPreferences(ctx context.Context, customerID int64) (*Preferences, error)
+
+	// SavePreferences stores p in full, replacing earlier preferences, and
+	// sets its UpdatedAt. An unknown customer is ErrNotFound.
+	SavePreferences(ctx context.Context, p *Preferences) error
+}
+
+// CustomerDelinquency is the delinquency flag one customer should have.
+type CustomerDelinquency struct {
+	CustomerID   int64
+	IsDelinquent bool
+}
+
+// DelinquencyNotice is the notice a delinquent customer is due for their
+// loan. Bucket is the DPD bucket DaysPastDue falls in; the customer is
+// notified again only once it changes.
+type DelinquencyNotice struct {
+	CustomerID  int64
+	LoanID      int64
+	DaysPastDue int
+	Bucket      string
+}
+
+// ListFilter selects, orders and pages the customers FindAll returns. A nil
+// flag matches either value; the zero value lists every customer by ID.
+type ListFilter struct {
+	Active     *bool
+	Delinquent *bool
+	// Sort is one of "id", "name", "created_at" or "updated_at", prefixed
+	// with "-" for descending order. Ties are broken by ID.
+	Sort string
+	// Limit caps the number of customers, 0 meaning no cap. Offset skips
+	// that many and needs a Limit.
+	Limit  int
+	Offset int
+	// AfterID keeps only customers with a larger ID, for keyset paging in
+	// ID order. Zero disables it.
+	AfterID int64
 }
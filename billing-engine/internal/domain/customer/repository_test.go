@@ -3,6 +3,7 @@ package customer
 import (
 	"context"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -68,12 +69,12 @@ func (_m *MockCustomerRepository) FindByLoanID(ctx context.Context, loanID int64
 	return r0, r1
 }
 
-func (_m *MockCustomerRepository) FindAll(ctx context.Context, activeOnly bool) ([]*Customer, error) {
-	ret := _m.Called(ctx, activeOnly)
+func (_m *MockCustomerRepository) FindAll(ctx context.Context, filter ListFilter) ([]*Customer, error) {
+	ret := _m.Called(ctx, filter)
 
 	var r0 []*Customer
-	if rf, ok := ret.Get(0).(func(context.Context, bool) []*Customer); ok {
-		r0 = rf(ctx, activeOnly)
+	if rf, ok := ret.Get(0).(func(context.Context, ListFilter) []*Customer); ok {
+		r0 = rf(ctx, filter)
 	} else {
 
 		if ret.Get(0) != nil {
@@ -82,8 +83,8 @@ func (_m *MockCustomerRepository) FindAll(ctx context.Context, activeOnly bool)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, bool) error); ok {
-		r1 = rf(ctx, activeOnly)
+	if rf, ok := ret.Get(1).(func(context.Context, ListFilter) error); ok {
+		r1 = rf(ctx, filter)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -104,6 +105,39 @@ func (_m *MockCustomerRepository) Delete(ctx context.Context, customerID int64)
 	return r0
 }
 
+func (_m *MockCustomerRepository) FindByExternalRef(ctx context.Context, externalRef string) (*Customer, error) {
+	ret := _m.Called(ctx, externalRef)
+
+	var r0 *Customer
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*Customer)
+	}
+
+	return r0, ret.Error(1)
+}
+
+func (_m *MockCustomerRepository) FindByNameContaining(ctx context.Context, part string, limit int) ([]*Customer, error) {
+	ret := _m.Called(ctx, part, limit)
+
+	var r0 []*Customer
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*Customer)
+	}
+
+	return r0, ret.Error(1)
+}
+
+func (_m *MockCustomerRepository) FindByPublicID(ctx context.Context, publicID uuid.UUID) (*Customer, error) {
+	ret := _m.Called(ctx, publicID)
+
+	var r0 *Customer
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*Customer)
+	}
+
+	return r0, ret.Error(1)
+}
+
 func (_m *MockCustomerRepository) SetDelinquencyStatus(ctx context.Context, customerID int64, isDelinquent bool) error {
 	ret := _m.Called(ctx, customerID, isDelinquent)
 
@@ -117,6 +151,33 @@ func (_m *MockCustomerRepository) SetDelinquencyStatus(ctx context.Context, cust
 	return r0
 }
 
+func (_m *MockCustomerRepository) SetDelinquencyStatusBulk(ctx context.Context, updates []CustomerDelinquency) ([]*Customer, error) {
+	ret := _m.Called(ctx, updates)
+
+	var r0 []*Customer
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*Customer)
+	}
+
+	return r0, ret.Error(1)
+}
+
+func (_m *MockCustomerRepository) MarkDelinquencyNotified(ctx context.Context, notices []DelinquencyNotice) ([]int64, error) {
+	ret := _m.Called(ctx, notices)
+
+	var r0 []int64
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]int64)
+	}
+
+	return r0, ret.Error(1)
+}
+
+func (_m *MockCustomerRepository) ClearDelinquencyNotices(ctx context.Context, customerIDs []int64) error {
+	ret := _m.Called(ctx, customerIDs)
+	return ret.Error(0)
+}
+
 func (_m *MockCustomerRepository) SetActiveStatus(ctx context.Context, customerID int64, isActive bool) error {
 	ret := _m.Called(ctx, customerID, isActive)
 
@@ -130,4 +191,36 @@ func (_m *MockCustomerRepository) SetActiveStatus(ctx context.Context, customerI
 	return r0
 }
 
+func (_m *MockCustomerRepository) GetPreferences(ctx context.Context, customerID int64) (*Preferences, error) {
+	ret := _m.Called(ctx, customerID)
+
+	var r0 *Preferences
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*Preferences)
+	}
+
+	return r0, ret.Error(1)
+}
+
+func (_m *MockCustomerRepository) SavePreferences(ctx context.Context, p *Preferences) error {
+	ret := _m.Called(ctx, p)
+	return ret.Error(0)
+}
+
+func (_m *MockCustomerRepository) SetRiskScore(ctx context.Context, a *RiskAssessment) error {
+	ret := _m.Called(ctx, a)
+	return ret.Error(0)
+}
+
+func (_m *MockCustomerRepository) MergeCustomers(ctx context.Context, targetID, sourceID int64) (*Merge, error) {
+	ret := _m.Called(ctx, targetID, sourceID)
+
+	var r0 *Merge
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*Merge)
+	}
+
+	return r0, ret.Error(1)
+}
+
 var _ CustomerRepository = (*MockCustomerRepository)(nil)
@@ -0,0 +1,60 @@
+package customer
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	MaxRiskScore       = 1000
+	MaxRiskGradeLength = 8
+)
+
+// RiskScoreReason says why a customer is sent to the credit bureau.
+type RiskScoreReason string
+
+const (
+	RiskScoreCustomerCreated RiskScoreReason = "CUSTOMER_CREATED"
+	RiskScoreLoanApplication RiskScoreReason = "LOAN_APPLICATION"
+)
+
+// riskGrade accepts the grades bureaus commonly use, such as "A", "B+" or
+// "E2".
+var riskGrade = regexp.MustCompile(`^[A-Z0-9][A-Z0-9+-]*$`)
+
+// RiskAssessment is the score and grade a credit bureau gave a customer.
+// ScoredAt is when the bureau scored them; it defaults to the time the
+// assessment is stored.
+type RiskAssessment struct {
+	CustomerID int64
+	Score      int
+	Grade      string
+	ScoredAt   time.Time
+}
+
+// normalize upper-cases the grade and rejects assessments that could not
+// have come from a bureau, including ones scored after now.
+func (a *RiskAssessment) normalize(now time.Time) error {
+	if a.Score < 0 || a.Score > MaxRiskScore {
+		return fmt.Errorf("%w: risk score must be between 0 and %d", apperrors.ErrInvalidArgument, MaxRiskScore)
+	}
+	a.Grade = strings.ToUpper(strings.TrimSpace(a.Grade))
+	if len(a.Grade) > MaxRiskGradeLength || !riskGrade.MatchString(a.Grade) {
+		return fmt.Errorf("%w: risk grade must be 1 to %d letters, digits, '+' or '-'", apperrors.ErrInvalidArgument, MaxRiskGradeLength)
+	}
+	if a.ScoredAt.IsZero() {
+		a.ScoredAt = now
+	}
+	if a.ScoredAt.After(now) {
+		return fmt.Errorf("%w: risk score cannot be dated in the future", apperrors.ErrInvalidArgument)
+	}
+	return nil
+}
+
+// Scored reports whether a risk score was stored for the customer.
+func (c *Customer) Scored() bool {
+	return c.RiskGrade != nil
+}
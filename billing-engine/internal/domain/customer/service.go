@@ -2,13 +2,18 @@ package customer
 
 import (
 	"billing-engine/internal/event"
+	"billing-engine/internal/infrastructure/monitoring"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
 	"context"
 	"errors"
 	"fmt"
 	"log/slog"
 	"os"
+	"slices"
 	"strings"
-	"time"
+
+	"github.com/google/uuid"
 )
 
 const (
@@ -17,26 +22,58 @@ const (
 )
 
 type CustomerService interface {
-	CreateNewCustomer(ctx context.Context, name, address string) (*Customer, error)
+	CreateNewCustomer(ctx context.Context, name, address, externalRef string, publicID uuid.UUID, force bool) (*Customer, error)
 	GetCustomer(ctx context.Context, customerID int64) (*Customer, error)
 	ListActiveCustomers(ctx context.Context) ([]*Customer, error)
+	ListActiveCustomersPage(ctx context.Context, afterID int64, limit int) ([]*Customer, error)
 	UpdateCustomerAddress(ctx context.Context, customerID int64, newAddress string) error
 	AssignLoanToCustomer(ctx context.Context, customerID int64, loanID int64) error
 	UpdateDelinquency(ctx context.Context, customerID int64, isDelinquent bool) error
+	UpdateDelinquencyBulk(ctx context.Context, updates []CustomerDelinquency) ([]*Customer, error)
+	// NotifyDelinquency raises a loan.delinquent event for each notice
+	// whose customer was not yet notified for its bucket, and returns
+	// those notices. Notices already sent for the same bucket are dropped.
+	NotifyDelinquency(ctx context.Context, notices []DelinquencyNotice) ([]DelinquencyNotice, error)
+	// ClearDelinquencyNotices is called for customers that are no longer
+	// delinquent, so that becoming delinquent again sends a new notice.
+	ClearDelinquencyNotices(ctx context.Context, customerIDs []int64) error
 	DeactivateCustomer(ctx context.Context, customerID int64) error
 	ReactivateCustomer(ctx context.Context, customerID int64) error
 	FindCustomerByLoan(ctx context.Context, loanID int64) (*Customer, error)
+	FindCustomerByExternalRef(ctx context.Context, externalRef string) (*Customer, error)
+	ResolveCustomerID(ctx context.Context, publicID uuid.UUID) (int64, error)
+	GetPreferences(ctx context.Context, customerID int64) (*Preferences, error)
+	// UpdatePreferences replaces the customer's communication preferences and
+	// publishes them for notify-service, which applies them before sending.
+	UpdatePreferences(ctx context.Context, p Preferences) (*Preferences, error)
+	// RequestRiskScore publishes a request for the credit bureau
+	// integration to score the customer. It does not wait for the score and
+	// only logs a request it could not publish. principal is the amount
+	// applied for, 0 when the request is not for a loan application.
+	RequestRiskScore(ctx context.Context, cust *Customer, reason RiskScoreReason, principal float64)
+	// UpdateRiskScore stores the bureau's assessment on the customer and
+	// returns the customer as stored.
+	UpdateRiskScore(ctx context.Context, a RiskAssessment) (*Customer, error)
+	// MergeCustomers merges the duplicate customer sourceID into targetID
+	// and publishes a customer.merged event, followed by an update event
+	// for each of the two.
+	MergeCustomers(ctx context.Context, targetID, sourceID int64) (*Merge, error)
 }
 
 var _ CustomerService = (*customerService)(nil)
 
 type customerService struct {
-	repo   CustomerRepository
-	pub    event.EventPublisher
-	logger *slog.Logger
+	repo       CustomerRepository
+	pub        event.EventPublisher
+	duplicates DuplicatePolicy
+	clock      clock.Clock
+	logger     *slog.Logger
 }
 
-func NewCustomerService(repo CustomerRepository, eventPublisher event.EventPublisher, logger *slog.Logger) CustomerService {
+// NewCustomerService builds the customer service. duplicates decides which
+// new customers are refused as repeating an existing one. The clock stamps
+// the published events; nil means the wall clock.
+func NewCustomerService(repo CustomerRepository, eventPublisher event.EventPublisher, duplicates DuplicatePolicy, clk clock.Clock, logger *slog.Logger) CustomerService {
 	if repo == nil {
 		panic("customer repository cannot be nil")
 	}
@@ -52,9 +89,11 @@ func NewCustomerService(repo CustomerRepository, eventPublisher event.EventPubli
 	}
 
 	return &customerService{
-		repo:   repo,
-		pub:    eventPublisher,
-		logger: logger.With(slog.String("component", "customerService")),
+		repo:       repo,
+		pub:        eventPublisher,
+		duplicates: duplicates,
+		clock:      clock.OrSystem(clk),
+		logger:     logger.With(slog.String("component", "customerService")),
 	}
 }
 
@@ -80,7 +119,7 @@ func (s *customerService) PublishCustomerUpdateEvent(ctx context.Context, custom
 		return
 	}
 	event := event.CustomerUpdatedEvent{
-		Timestamp: time.Now(),
+		Timestamp: s.clock.Now(),
 		Payload:   NewCustomerEventPayload(customer),
 	}
 	s.logger.With(slog.Int64("customerID", customer.CustomerID))
@@ -92,7 +131,12 @@ func (s *customerService) PublishCustomerUpdateEvent(ctx context.Context, custom
 	}
 }
 
-func (s *customerService) CreateNewCustomer(ctx context.Context, name, address string) (*Customer, error) {
+// CreateNewCustomer validates and stores a new customer. A non-nil publicID
+// chosen by the client makes retries safe: if a customer with that ID already
+// exists it is returned unchanged and no creation event is published. A
+// customer that repeats an active one under the duplicate policy is refused
+// with a conflict listing the matches, unless force is set.
+func (s *customerService) CreateNewCustomer(ctx context.Context, name, address, externalRef string, publicID uuid.UUID, force bool) (*Customer, error) {
 	s.logger.InfoContext(ctx, "Attempting to create new customer")
 
 	name = strings.TrimSpace(name)
@@ -110,13 +154,37 @@ func (s *customerService) CreateNewCustomer(ctx context.Context, name, address s
 	s.logger = s.logger.With(slog.String("validated_name", name), slog.String("validated_address", address))
 	s.logger.InfoContext(ctx, inputValidationPassed)
 
+	if publicID != uuid.Nil {
+		existing, err := s.repo.FindByPublicID(ctx, publicID)
+		switch {
+		case err == nil:
+			s.logger.InfoContext(ctx, "Customer with this public ID already exists, returning it", slog.Int64("customerID", existing.CustomerID))
+			return existing, nil
+		case !errors.Is(err, ErrNotFound):
+			s.logger.ErrorContext(ctx, "Repository failed to look up customer public ID", slog.Any("error", err))
+			return nil, fmt.Errorf("failed to look up customer public ID: %w", err)
+		}
+	} else {
+		publicID = uuid.New()
+	}
+
+	if !force {
+		if err := s.checkDuplicates(ctx, name, address); err != nil {
+			return nil, err
+		}
+	}
+
 	customer := &Customer{
+		PublicID:     publicID,
 		Name:         name,
 		Address:      address,
 		IsDelinquent: false,
 		Active:       true,
 		LoanID:       nil,
 	}
+	if externalRef = strings.TrimSpace(externalRef); externalRef != "" {
+		customer.ExternalRef = &externalRef
+	}
 	s.logger.InfoContext(ctx, "Customer domain object created")
 
 	s.logger.InfoContext(ctx, "Calling repository Save")
@@ -129,7 +197,7 @@ func (s *customerService) CreateNewCustomer(ctx context.Context, name, address s
 	s.logger = s.logger.With(slog.Int64("customerID", customer.CustomerID))
 	s.logger.InfoContext(ctx, "Successfully saved new customer, publishing creation event")
 	createdEvent := event.CustomerCreatedEvent{
-		Timestamp: time.Now(),
+		Timestamp: s.clock.Now(),
 		Payload:   NewCustomerEventPayload(customer),
 	}
 	if pubErr := s.pub.PublishCustomerCreated(ctx, createdEvent); pubErr != nil {
@@ -137,11 +205,39 @@ func (s *customerService) CreateNewCustomer(ctx context.Context, name, address s
 	} else {
 		s.logger.InfoContext(ctx, "Successfully published customer creation event")
 	}
+	s.RequestRiskScore(ctx, customer, RiskScoreCustomerCreated, 0)
 	s.logger = s.logger.With(slog.Int64("customerID", customer.CustomerID))
 	s.logger.InfoContext(ctx, "Successfully created new customer")
 	return customer, nil
 }
 
+// checkDuplicates refuses a new customer with name and address that matches
+// active customers under the duplicate policy.
+func (s *customerService) checkDuplicates(ctx context.Context, name, address string) error {
+	if !s.duplicates.enabled() {
+		return nil
+	}
+	found, err := s.repo.FindByNameContaining(ctx, s.duplicates.probe(name), duplicateScanLimit)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Repository failed to look up duplicate customers", slog.Any("error", err))
+		return fmt.Errorf("failed to look up duplicate customers: %w", err)
+	}
+	var ids []int64
+	for _, c := range found {
+		if s.duplicates.matches(c, name, address) {
+			ids = append(ids, c.CustomerID)
+			if len(ids) == MaxDuplicateCandidates {
+				break
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	s.logger.WarnContext(ctx, "New customer repeats existing customers", slog.String("check", string(s.duplicates.Check)), slog.Any("candidateIDs", ids))
+	return duplicateError(ids)
+}
+
 func (s *customerService) GetCustomer(ctx context.Context, customerID int64) (*Customer, error) {
 	s.logger.InfoContext(ctx, "Attempting to get customer by ID")
 
@@ -167,8 +263,9 @@ func (s *customerService) ListActiveCustomers(ctx context.Context) ([]*Customer,
 
 	s.logger.InfoContext(ctx, "Attempting to list all active customers")
 
-	s.logger.InfoContext(ctx, "Calling repository FindAll", slog.Bool("activeOnly", true))
-	customers, err := s.repo.FindAll(ctx, true)
+	active := true
+	s.logger.InfoContext(ctx, "Calling repository FindAll", slog.Bool("activeOnly", active))
+	customers, err := s.repo.FindAll(ctx, ListFilter{Active: &active})
 	if err != nil {
 		s.logger.ErrorContext(ctx, "Repository error listing active customers", slog.Any("error", err))
 		return nil, fmt.Errorf("failed to list active customers: %w", err)
@@ -178,6 +275,22 @@ func (s *customerService) ListActiveCustomers(ctx context.Context) ([]*Customer,
 	return customers, nil
 }
 
+// ListActiveCustomersPage lists at most limit active customers with an ID
+// above afterID, in ID order, so callers can page with the last ID seen.
+func (s *customerService) ListActiveCustomersPage(ctx context.Context, afterID int64, limit int) ([]*Customer, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("%w: page size must be positive", apperrors.ErrInvalidArgument)
+	}
+
+	active := true
+	customers, err := s.repo.FindAll(ctx, ListFilter{Active: &active, AfterID: afterID, Limit: limit})
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Repository error listing a page of active customers", slog.Int64("afterID", afterID), slog.Any("error", err))
+		return nil, fmt.Errorf("failed to list active customers: %w", err)
+	}
+	return customers, nil
+}
+
 func (s *customerService) UpdateCustomerAddress(ctx context.Context, customerID int64, newAddress string) error {
 
 	s.logger.InfoContext(ctx, "Attempting to update customer address")
@@ -320,6 +433,82 @@ func (s *customerService) UpdateDelinquency(ctx context.Context, customerID int6
 	return nil
 }
 
+// UpdateDelinquencyBulk stores all updates at once and publishes an update
+// event for each customer whose flag changed in a single batch, built from
+// the rows the repository returned rather than a fresh read per customer.
+func (s *customerService) UpdateDelinquencyBulk(ctx context.Context, updates []CustomerDelinquency) ([]*Customer, error) {
+	if len(updates) == 0 {
+		return nil, nil
+	}
+	s.logger.InfoContext(ctx, "Calling repository SetDelinquencyStatusBulk", slog.Int("count", len(updates)))
+	changed, err := s.repo.SetDelinquencyStatusBulk(ctx, updates)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Repository error updating delinquency statuses", slog.Any("error", err))
+		return nil, fmt.Errorf("failed to update delinquency for %d customers: %w", len(updates), err)
+	}
+	if len(changed) > 0 {
+		messages := make([]event.Message, len(changed))
+		for i, c := range changed {
+			monitoring.RecordDelinquencyChange(c.IsDelinquent)
+			messages[i] = event.CustomerUpdatedMessage(event.CustomerUpdatedEvent{Timestamp: s.clock.Now(), Payload: NewCustomerEventPayload(c)})
+		}
+		if err := s.pub.PublishBatch(ctx, messages); err != nil {
+			s.logger.ErrorContext(ctx, "Failed to publish customer update events", slog.Int("count", len(messages)), slog.Any("error", err))
+		}
+	}
+	s.logger.InfoContext(ctx, "Successfully updated customer delinquency statuses", slog.Int("changed", len(changed)))
+	return changed, nil
+}
+
+// NotifyDelinquency records the notices before publishing them: a failed
+// publish stays in the event log for a replay, while a failed store would
+// send the same notice again on the next run.
+func (s *customerService) NotifyDelinquency(ctx context.Context, notices []DelinquencyNotice) ([]DelinquencyNotice, error) {
+	if len(notices) == 0 {
+		return nil, nil
+	}
+	notified, err := s.repo.MarkDelinquencyNotified(ctx, notices)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Repository error recording delinquency notices", slog.Any("error", err))
+		return nil, fmt.Errorf("failed to record delinquency notices for %d customers: %w", len(notices), err)
+	}
+	due := make([]DelinquencyNotice, 0, len(notified))
+	for _, n := range notices {
+		if slices.Contains(notified, n.CustomerID) {
+			due = append(due, n)
+		}
+	}
+	if len(due) > 0 {
+		now := s.clock.Now()
+		messages := make([]event.Message, len(due))
+		for i, n := range due {
+			messages[i] = event.LoanDelinquentMessage(event.LoanDelinquentEvent{
+				LoanID:      n.LoanID,
+				CustomerID:  n.CustomerID,
+				DaysPastDue: n.DaysPastDue,
+				Bucket:      n.Bucket,
+				Timestamp:   now,
+			})
+		}
+		if err := s.pub.PublishBatch(ctx, messages); err != nil {
+			s.logger.ErrorContext(ctx, "Failed to publish delinquency notices", slog.Int("count", len(messages)), slog.Any("error", err))
+		}
+	}
+	s.logger.InfoContext(ctx, "Delinquency notices raised", slog.Int("checked", len(notices)), slog.Int("sent", len(due)))
+	return due, nil
+}
+
+func (s *customerService) ClearDelinquencyNotices(ctx context.Context, customerIDs []int64) error {
+	if len(customerIDs) == 0 {
+		return nil
+	}
+	if err := s.repo.ClearDelinquencyNotices(ctx, customerIDs); err != nil {
+		s.logger.ErrorContext(ctx, "Repository error clearing delinquency notices", slog.Any("error", err))
+		return fmt.Errorf("failed to clear delinquency notices for %d customers: %w", len(customerIDs), err)
+	}
+	return nil
+}
+
 func (s *customerService) DeactivateCustomer(ctx context.Context, customerID int64) error {
 
 	s.logger.InfoContext(ctx, "Attempting to deactivate customer")
@@ -392,3 +581,166 @@ func (s *customerService) FindCustomerByLoan(ctx context.Context, loanID int64)
 	s.logger.InfoContext(ctx, "Successfully found customer by loan ID")
 	return customer, nil
 }
+
+func (s *customerService) FindCustomerByExternalRef(ctx context.Context, externalRef string) (*Customer, error) {
+
+	s.logger.InfoContext(ctx, "Attempting to find customer by external reference")
+
+	externalRef = strings.TrimSpace(externalRef)
+	if externalRef == "" {
+		s.logger.WarnContext(ctx, "Validation failed: external reference is empty")
+		return nil, errors.New("external reference cannot be empty")
+	}
+
+	customer, err := s.repo.FindByExternalRef(ctx, externalRef)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			s.logger.WarnContext(ctx, "Customer not found by repository for this external reference")
+			return nil, ErrNotFound
+		}
+		s.logger.ErrorContext(ctx, "Repository error finding customer by external reference", slog.Any("error", err))
+		return nil, fmt.Errorf("failed to find customer by external reference %q: %w", externalRef, err)
+	}
+
+	s.logger.InfoContext(ctx, "Successfully found customer by external reference", slog.Int64("customerID", customer.CustomerID))
+	return customer, nil
+}
+
+func (s *customerService) ResolveCustomerID(ctx context.Context, publicID uuid.UUID) (int64, error) {
+
+	customer, err := s.repo.FindByPublicID(ctx, publicID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) || errors.Is(err, apperrors.ErrNotFound) {
+			s.logger.WarnContext(ctx, "Customer not found by repository for this public ID")
+			return 0, fmt.Errorf("%w: customer with public ID %s not found", apperrors.ErrNotFound, publicID)
+		}
+		s.logger.ErrorContext(ctx, "Repository error resolving customer public ID", slog.Any("error", err))
+		return 0, fmt.Errorf("failed to resolve customer public ID %s: %w", publicID, err)
+	}
+	return customer.CustomerID, nil
+}
+
+func (s *customerService) GetPreferences(ctx context.Context, customerID int64) (*Preferences, error) {
+	prefs, err := s.repo.GetPreferences(ctx, customerID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) || errors.Is(err, apperrors.ErrNotFound) {
+			s.logger.WarnContext(ctx, customerNotFound)
+			return nil, fmt.Errorf("%w: customer %d", apperrors.ErrNotFound, customerID)
+		}
+		s.logger.ErrorContext(ctx, "Repository error getting customer preferences", slog.Any("error", err))
+		return nil, fmt.Errorf("failed to get preferences of customer %d: %w", customerID, err)
+	}
+	return prefs, nil
+}
+
+func (s *customerService) UpdatePreferences(ctx context.Context, p Preferences) (*Preferences, error) {
+	if err := p.normalize(); err != nil {
+		s.logger.WarnContext(ctx, "Validation failed for customer preferences", slog.Any("error", err))
+		return nil, err
+	}
+	if err := s.repo.SavePreferences(ctx, &p); err != nil {
+		if errors.Is(err, ErrNotFound) || errors.Is(err, apperrors.ErrNotFound) {
+			s.logger.WarnContext(ctx, customerNotFound)
+			return nil, fmt.Errorf("%w: customer %d", apperrors.ErrNotFound, p.CustomerID)
+		}
+		s.logger.ErrorContext(ctx, "Repository error saving customer preferences", slog.Any("error", err))
+		return nil, fmt.Errorf("failed to save preferences of customer %d: %w", p.CustomerID, err)
+	}
+
+	changed := event.PreferencesChangedMessage(event.CustomerPreferencesChangedEvent{
+		CustomerID:          p.CustomerID,
+		PreferredChannel:    p.PreferredChannel,
+		Language:            p.Language,
+		MarketingOptOut:     p.MarketingOptOut,
+		TransactionalOptOut: p.TransactionalOptOut,
+		UpdatedAt:           p.UpdatedAt,
+		Timestamp:           s.clock.Now(),
+	})
+	if err := s.pub.PublishBatch(ctx, []event.Message{changed}); err != nil {
+		s.logger.ErrorContext(ctx, "Preferences saved, but FAILED to publish preferences event", slog.Int64("customerID", p.CustomerID), slog.Any("error", err))
+	}
+	s.logger.InfoContext(ctx, "Customer preferences updated", slog.Int64("customerID", p.CustomerID),
+		slog.String("preferredChannel", p.PreferredChannel), slog.Bool("marketingOptOut", p.MarketingOptOut), slog.Bool("transactionalOptOut", p.TransactionalOptOut))
+	return &p, nil
+}
+
+func (s *customerService) RequestRiskScore(ctx context.Context, cust *Customer, reason RiskScoreReason, principal float64) {
+	requested := event.CustomerRiskScoreRequestedEvent{
+		CustomerID:  cust.CustomerID,
+		ExternalRef: cust.ExternalRef,
+		Name:        cust.Name,
+		Address:     cust.Address,
+		Reason:      string(reason),
+		Timestamp:   s.clock.Now(),
+	}
+	if cust.PublicID != uuid.Nil {
+		requested.PublicID = cust.PublicID.String()
+	}
+	if principal > 0 {
+		requested.Principal = &principal
+	}
+	if err := s.pub.PublishBatch(ctx, []event.Message{event.RiskScoreRequestedMessage(requested)}); err != nil {
+		s.logger.ErrorContext(ctx, "FAILED to publish risk score request", slog.Int64("customerID", cust.CustomerID), slog.String("reason", string(reason)), slog.Any("error", err))
+		return
+	}
+	s.logger.InfoContext(ctx, "Risk score requested", slog.Int64("customerID", cust.CustomerID), slog.String("reason", string(reason)))
+}
+
+func (s *customerService) UpdateRiskScore(ctx context.Context, a RiskAssessment) (*Customer, error) {
+	if err := a.normalize(s.clock.Now()); err != nil {
+		s.logger.WarnContext(ctx, "Validation failed for risk assessment", slog.Any("error", err))
+		return nil, err
+	}
+	if err := s.repo.SetRiskScore(ctx, &a); err != nil {
+		if errors.Is(err, ErrNotFound) || errors.Is(err, apperrors.ErrNotFound) {
+			s.logger.WarnContext(ctx, customerNotFound)
+			return nil, fmt.Errorf("%w: customer %d", apperrors.ErrNotFound, a.CustomerID)
+		}
+		s.logger.ErrorContext(ctx, "Repository error saving risk score", slog.Any("error", err))
+		return nil, fmt.Errorf("failed to save risk score of customer %d: %w", a.CustomerID, err)
+	}
+	s.logger.InfoContext(ctx, "Customer risk score updated", slog.Int64("customerID", a.CustomerID), slog.Int("score", a.Score), slog.String("grade", a.Grade))
+	return s.GetCustomer(ctx, a.CustomerID)
+}
+
+func (s *customerService) MergeCustomers(ctx context.Context, targetID, sourceID int64) (*Merge, error) {
+	logger := s.logger.With(slog.Int64("targetID", targetID), slog.Int64("sourceID", sourceID))
+	if sourceID <= 0 {
+		return nil, fmt.Errorf("%w: source customer ID must be a positive number", apperrors.ErrInvalidArgument)
+	}
+	if targetID == sourceID {
+		return nil, fmt.Errorf("%w: a customer cannot be merged into itself", apperrors.ErrInvalidArgument)
+	}
+
+	merge, err := s.repo.MergeCustomers(ctx, targetID, sourceID)
+	if err != nil {
+		switch {
+		case errors.Is(err, apperrors.ErrNotFound):
+			logger.WarnContext(ctx, customerNotFound, slog.Any("error", err))
+			return nil, err
+		case errors.Is(err, apperrors.ErrConflict), errors.Is(err, apperrors.ErrInvalidArgument):
+			logger.WarnContext(ctx, "Customer merge refused", slog.Any("error", err))
+			return nil, err
+		}
+		logger.ErrorContext(ctx, "Repository error merging customers", slog.Any("error", err))
+		return nil, fmt.Errorf("failed to merge customer %d into %d: %w", sourceID, targetID, err)
+	}
+
+	now := s.clock.Now()
+	messages := []event.Message{
+		event.CustomerMergedMessage(event.CustomerMergedEvent{
+			TargetID:  targetID,
+			SourceID:  sourceID,
+			LoanID:    merge.LoanID,
+			MergedAt:  *merge.Source.MergedAt,
+			Timestamp: now,
+		}),
+		event.CustomerUpdatedMessage(event.CustomerUpdatedEvent{Timestamp: now, Payload: NewCustomerEventPayload(merge.Target)}),
+		event.CustomerUpdatedMessage(event.CustomerUpdatedEvent{Timestamp: now, Payload: NewCustomerEventPayload(merge.Source)}),
+	}
+	if err := s.pub.PublishBatch(ctx, messages); err != nil {
+		logger.ErrorContext(ctx, "Customers merged, but FAILED to publish merge events", slog.Any("error", err))
+	}
+	logger.InfoContext(ctx, "Customers merged", slog.Any("moved", merge.Moved))
+	return merge, nil
+}
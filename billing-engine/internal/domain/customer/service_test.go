@@ -3,6 +3,9 @@ package customer_test
 import (
 	"billing-engine/internal/domain/customer"
 	"billing-engine/internal/event"
+	"billing-engine/internal/infrastructure/monitoring"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
 	"context"
 	"errors"
 	"fmt"
@@ -11,6 +14,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -34,13 +39,19 @@ func (m *MockEventPublisher) PublishCustomerUpdated(ctx context.Context, event e
 	return args.Error(0)
 }
 
+func (m *MockEventPublisher) PublishBatch(ctx context.Context, messages []event.Message) error {
+	args := m.Called(ctx, messages)
+	return args.Error(0)
+}
+
 func setupTest() (*customer.MockCustomerRepository, customer.CustomerService) {
 	mockRepo := new(customer.MockCustomerRepository)
 	mockEvent := new(MockEventPublisher)
 	mockEvent.On("PublishCustomerCreated", mock.Anything, mock.Anything).Return(nil)
 	mockEvent.On("PublishCustomerUpdated", mock.Anything, mock.Anything).Return(nil)
+	mockEvent.On("PublishBatch", mock.Anything, mock.Anything).Return(nil)
 	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
-	service := customer.NewCustomerService(mockRepo, mockEvent, logger)
+	service := customer.NewCustomerService(mockRepo, mockEvent, customer.DuplicatePolicy{}, clock.System(), logger)
 	return mockRepo, service
 }
 
@@ -79,7 +90,7 @@ func TestCustomerServiceCreateNewCustomer(t *testing.T) {
 			return match
 		})).Return(nil).Once()
 
-		createdCustomer, err := service.CreateNewCustomer(ctx, name, address)
+		createdCustomer, err := service.CreateNewCustomer(ctx, name, address, "", uuid.Nil, false)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, createdCustomer)
@@ -96,9 +107,23 @@ func TestCustomerServiceCreateNewCustomer(t *testing.T) {
 		mockRepo.AssertExpectations(t)
 	})
 
+	t.Run("Success - With External Reference", func(t *testing.T) {
+		mockRepo, service := setupTest()
+
+		mockRepo.On("Save", ctx, mock.MatchedBy(func(c *customer.Customer) bool {
+			return c.ExternalRef != nil && *c.ExternalRef == "CRM-0001"
+		})).Return(nil).Once()
+
+		createdCustomer, err := service.CreateNewCustomer(ctx, "Test User", "123 Test St", " CRM-0001 ", uuid.Nil, false)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "CRM-0001", *createdCustomer.ExternalRef)
+		mockRepo.AssertExpectations(t)
+	})
+
 	t.Run("Error - Empty Name", func(t *testing.T) {
 		mockRepo, service := setupTest()
-		_, err := service.CreateNewCustomer(ctx, "", "Some Address")
+		_, err := service.CreateNewCustomer(ctx, "", "Some Address", "", uuid.Nil, false)
 		assert.Error(t, err)
 		assert.EqualError(t, err, "customer name cannot be empty")
 		mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
@@ -106,7 +131,7 @@ func TestCustomerServiceCreateNewCustomer(t *testing.T) {
 
 	t.Run("Error - Empty Address", func(t *testing.T) {
 		mockRepo, service := setupTest()
-		_, err := service.CreateNewCustomer(ctx, "Some Name", "  ")
+		_, err := service.CreateNewCustomer(ctx, "Some Name", "  ", "", uuid.Nil, false)
 		assert.Error(t, err)
 		assert.EqualError(t, err, "customer address cannot be empty")
 		mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
@@ -118,7 +143,7 @@ func TestCustomerServiceCreateNewCustomer(t *testing.T) {
 
 		mockRepo.On("Save", ctx, mock.AnythingOfType("*customer.Customer")).Return(dbError).Once()
 
-		createdCustomer, err := service.CreateNewCustomer(ctx, "Valid Name", "Valid Address")
+		createdCustomer, err := service.CreateNewCustomer(ctx, "Valid Name", "Valid Address", "", uuid.Nil, false)
 
 		assert.Error(t, err)
 		assert.Nil(t, createdCustomer)
@@ -176,6 +201,8 @@ func TestCustomerServiceGetCustomer(t *testing.T) {
 
 func TestCustomerServiceListActiveCustomers(t *testing.T) {
 	ctx := context.Background()
+	active := true
+	activeOnly := customer.ListFilter{Active: &active}
 
 	t.Run("Success", func(t *testing.T) {
 		mockRepo, service := setupTest()
@@ -184,7 +211,7 @@ func TestCustomerServiceListActiveCustomers(t *testing.T) {
 			{CustomerID: 2, Name: "Bob", Active: true},
 		}
 
-		mockRepo.On("FindAll", ctx, true).Return(expectedCustomers, nil).Once()
+		mockRepo.On("FindAll", ctx, activeOnly).Return(expectedCustomers, nil).Once()
 
 		customers, err := service.ListActiveCustomers(ctx)
 
@@ -197,7 +224,7 @@ func TestCustomerServiceListActiveCustomers(t *testing.T) {
 		mockRepo, service := setupTest()
 		expectedCustomers := []*customer.Customer{}
 
-		mockRepo.On("FindAll", ctx, true).Return(expectedCustomers, nil).Once()
+		mockRepo.On("FindAll", ctx, activeOnly).Return(expectedCustomers, nil).Once()
 
 		customers, err := service.ListActiveCustomers(ctx)
 
@@ -211,7 +238,7 @@ func TestCustomerServiceListActiveCustomers(t *testing.T) {
 		mockRepo, service := setupTest()
 		dbError := errors.New("query failed")
 
-		mockRepo.On("FindAll", ctx, true).Return(nil, dbError).Once()
+		mockRepo.On("FindAll", ctx, activeOnly).Return(nil, dbError).Once()
 
 		customers, err := service.ListActiveCustomers(ctx)
 
@@ -223,6 +250,34 @@ func TestCustomerServiceListActiveCustomers(t *testing.T) {
 	})
 }
 
+func TestCustomerServiceListActiveCustomersPage(t *testing.T) {
+	ctx := context.Background()
+	active := true
+
+	t.Run("Success", func(t *testing.T) {
+		mockRepo, service := setupTest()
+		expectedCustomers := []*customer.Customer{{CustomerID: 8, Name: "Alice", Active: true}}
+
+		mockRepo.On("FindAll", ctx, customer.ListFilter{Active: &active, AfterID: 7, Limit: 1}).Return(expectedCustomers, nil).Once()
+
+		customers, err := service.ListActiveCustomersPage(ctx, 7, 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, expectedCustomers, customers)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Error - No Page Size", func(t *testing.T) {
+		mockRepo, service := setupTest()
+
+		customers, err := service.ListActiveCustomersPage(ctx, 0, 0)
+
+		assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+		assert.Nil(t, customers)
+		mockRepo.AssertNotCalled(t, "FindAll", mock.Anything, mock.Anything)
+	})
+}
+
 func TestCustomerServiceUpdateCustomerAddress(t *testing.T) {
 	ctx := context.Background()
 	customerID := int64(55)
@@ -522,6 +577,118 @@ func TestCustomerServiceUpdateDelinquency(t *testing.T) {
 	}
 }
 
+func TestCustomerServiceUpdateDelinquencyBulk(t *testing.T) {
+	ctx := context.Background()
+	updates := []customer.CustomerDelinquency{{CustomerID: 1, IsDelinquent: true}, {CustomerID: 2, IsDelinquent: false}}
+
+	t.Run("publishes the changed customers in one batch", func(t *testing.T) {
+		mockRepo := new(customer.MockCustomerRepository)
+		mockEvent := new(MockEventPublisher)
+		service := customer.NewCustomerService(mockRepo, mockEvent, customer.DuplicatePolicy{}, clock.System(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+		changed := []*customer.Customer{{CustomerID: 1, IsDelinquent: true}}
+		mockRepo.On("SetDelinquencyStatusBulk", ctx, updates).Return(changed, nil).Once()
+		mockEvent.On("PublishBatch", ctx, mock.MatchedBy(func(messages []event.Message) bool {
+			if len(messages) != 1 || messages[0].Type != event.TypeCustomerUpdated || messages[0].EventID == "" {
+				return false
+			}
+			payload := messages[0].Payload.(event.CustomerUpdatedEvent).Payload
+			return payload.CustomerID == 1 && payload.IsDelinquent
+		})).Return(nil).Once()
+		flagged := monitoring.Business.DelinquencyChangesTotal.WithLabelValues("became_delinquent")
+		flaggedBefore := testutil.ToFloat64(flagged)
+
+		got, err := service.UpdateDelinquencyBulk(ctx, updates)
+
+		assert.NoError(t, err)
+		assert.Equal(t, changed, got)
+		assert.Equal(t, flaggedBefore+1, testutil.ToFloat64(flagged), "only the changed customer counts")
+		mockRepo.AssertExpectations(t)
+		mockEvent.AssertExpectations(t)
+	})
+
+	t.Run("skips the repository when nothing changes", func(t *testing.T) {
+		mockRepo, service := setupTest()
+
+		got, err := service.UpdateDelinquencyBulk(ctx, nil)
+
+		assert.NoError(t, err)
+		assert.Empty(t, got)
+		mockRepo.AssertNotCalled(t, "SetDelinquencyStatusBulk", mock.Anything, mock.Anything)
+	})
+
+	t.Run("wraps repository errors", func(t *testing.T) {
+		mockRepo, service := setupTest()
+		mockRepo.On("SetDelinquencyStatusBulk", ctx, updates).Return(nil, apperrors.ErrDatabase).Once()
+
+		_, err := service.UpdateDelinquencyBulk(ctx, updates)
+
+		assert.ErrorIs(t, err, apperrors.ErrDatabase)
+	})
+}
+
+func TestCustomerServiceNotifyDelinquency(t *testing.T) {
+	ctx := context.Background()
+	notices := []customer.DelinquencyNotice{
+		{CustomerID: 1, LoanID: 10, DaysPastDue: 35, Bucket: "31-60"},
+		{CustomerID: 2, LoanID: 20, DaysPastDue: 9, Bucket: "1-30"},
+	}
+
+	t.Run("publishes only the notices not sent for the bucket yet", func(t *testing.T) {
+		mockRepo := new(customer.MockCustomerRepository)
+		mockEvent := new(MockEventPublisher)
+		service := customer.NewCustomerService(mockRepo, mockEvent, customer.DuplicatePolicy{}, clock.System(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+		mockRepo.On("MarkDelinquencyNotified", ctx, notices).Return([]int64{1}, nil).Once()
+		mockEvent.On("PublishBatch", ctx, mock.MatchedBy(func(messages []event.Message) bool {
+			if len(messages) != 1 || messages[0].Type != event.TypeLoanDelinquent || messages[0].EntityID != 1 {
+				return false
+			}
+			e := messages[0].Payload.(event.LoanDelinquentEvent)
+			return e.LoanID == 10 && e.DaysPastDue == 35 && e.Bucket == "31-60"
+		})).Return(nil).Once()
+
+		sent, err := service.NotifyDelinquency(ctx, notices)
+
+		assert.NoError(t, err)
+		assert.Equal(t, notices[:1], sent)
+		mockRepo.AssertExpectations(t)
+		mockEvent.AssertExpectations(t)
+	})
+
+	t.Run("publishes nothing when every customer was notified", func(t *testing.T) {
+		mockRepo := new(customer.MockCustomerRepository)
+		mockEvent := new(MockEventPublisher)
+		service := customer.NewCustomerService(mockRepo, mockEvent, customer.DuplicatePolicy{}, clock.System(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+		mockRepo.On("MarkDelinquencyNotified", ctx, notices).Return([]int64{}, nil).Once()
+
+		sent, err := service.NotifyDelinquency(ctx, notices)
+
+		assert.NoError(t, err)
+		assert.Empty(t, sent)
+		mockEvent.AssertNotCalled(t, "PublishBatch", mock.Anything, mock.Anything)
+	})
+
+	t.Run("wraps repository errors", func(t *testing.T) {
+		mockRepo, service := setupTest()
+		mockRepo.On("MarkDelinquencyNotified", ctx, notices).Return(nil, apperrors.ErrDatabase).Once()
+
+		_, err := service.NotifyDelinquency(ctx, notices)
+
+		assert.ErrorIs(t, err, apperrors.ErrDatabase)
+	})
+}
+
+func TestCustomerServiceClearDelinquencyNotices(t *testing.T) {
+	ctx := context.Background()
+	mockRepo, service := setupTest()
+	mockRepo.On("ClearDelinquencyNotices", ctx, []int64{3, 4}).Return(nil).Once()
+	mockRepo.On("ClearDelinquencyNotices", ctx, []int64{5}).Return(apperrors.ErrDatabase).Once()
+
+	assert.NoError(t, service.ClearDelinquencyNotices(ctx, []int64{3, 4}))
+	assert.NoError(t, service.ClearDelinquencyNotices(ctx, nil))
+	assert.ErrorIs(t, service.ClearDelinquencyNotices(ctx, []int64{5}), apperrors.ErrDatabase)
+	mockRepo.AssertExpectations(t)
+}
+
 func TestCustomerServiceDeactivateCustomer(t *testing.T) {
 	ctx := context.Background()
 	customerID := int64(99)
@@ -637,18 +804,456 @@ func TestCustomerServiceFindCustomerByLoan(t *testing.T) {
 	})
 }
 
+func TestCustomerServiceFindCustomerByExternalRef(t *testing.T) {
+	ctx := context.Background()
+	externalRef := "CRM-0001"
+
+	t.Run("Success", func(t *testing.T) {
+		mockRepo, service := setupTest()
+		expectedCustomer := &customer.Customer{CustomerID: 7, Name: "Found By Ref", Active: true, ExternalRef: &externalRef}
+
+		mockRepo.On("FindByExternalRef", ctx, externalRef).Return(expectedCustomer, nil).Once()
+
+		cust, err := service.FindCustomerByExternalRef(ctx, "  "+externalRef+" ")
+
+		assert.NoError(t, err)
+		assert.Equal(t, expectedCustomer, cust)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Error - Empty Reference", func(t *testing.T) {
+		mockRepo, service := setupTest()
+
+		cust, err := service.FindCustomerByExternalRef(ctx, " ")
+
+		assert.EqualError(t, err, "external reference cannot be empty")
+		assert.Nil(t, cust)
+		mockRepo.AssertNotCalled(t, "FindByExternalRef", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Error - Not Found", func(t *testing.T) {
+		mockRepo, service := setupTest()
+
+		mockRepo.On("FindByExternalRef", ctx, externalRef).Return(nil, customer.ErrNotFound).Once()
+
+		cust, err := service.FindCustomerByExternalRef(ctx, externalRef)
+
+		assert.ErrorIs(t, err, customer.ErrNotFound)
+		assert.Nil(t, cust)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestCustomerServiceCreateNewCustomerPublicID(t *testing.T) {
+	ctx := context.Background()
+	publicID := uuid.New()
+
+	t.Run("Replay returns existing customer", func(t *testing.T) {
+		mockRepo, service := setupTest()
+		existing := &customer.Customer{CustomerID: 9, PublicID: publicID, Name: "Replayed", Active: true}
+
+		mockRepo.On("FindByPublicID", ctx, publicID).Return(existing, nil).Once()
+
+		cust, err := service.CreateNewCustomer(ctx, "Replayed", "1 Main St", "", publicID, false)
+
+		assert.NoError(t, err)
+		assert.Equal(t, existing, cust)
+		mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Stores client supplied public ID", func(t *testing.T) {
+		mockRepo, service := setupTest()
+
+		mockRepo.On("FindByPublicID", ctx, publicID).Return(nil, customer.ErrNotFound).Once()
+		mockRepo.On("Save", ctx, mock.MatchedBy(func(c *customer.Customer) bool {
+			c.CustomerID = 10
+			return c.PublicID == publicID
+		})).Return(nil).Once()
+
+		cust, err := service.CreateNewCustomer(ctx, "New Customer", "1 Main St", "", publicID, false)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(10), cust.CustomerID)
+		assert.Equal(t, publicID, cust.PublicID)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestCustomerServiceCreateNewCustomerDuplicates(t *testing.T) {
+	ctx := context.Background()
+	discard := slog.New(slog.NewTextHandler(io.Discard, nil))
+	existing := []*customer.Customer{
+		{CustomerID: 3, Name: "Budi Santoso", Address: "Jalan Merdeka No. 5", Active: true},
+		{CustomerID: 8, Name: "Budi Santoso", Address: "Jl. Sudirman 12", Active: true},
+		{CustomerID: 11, Name: "budi santoso", Address: "jl merdeka 5", Active: true},
+	}
+	setup := func(check customer.DuplicateCheck) (*customer.MockCustomerRepository, customer.CustomerService) {
+		mockRepo := new(customer.MockCustomerRepository)
+		mockEvent := new(MockEventPublisher)
+		mockEvent.On("PublishCustomerCreated", mock.Anything, mock.Anything).Return(nil)
+		mockEvent.On("PublishBatch", mock.Anything, mock.Anything).Return(nil)
+		return mockRepo, customer.NewCustomerService(mockRepo, mockEvent, customer.DuplicatePolicy{Check: check}, clock.System(), discard)
+	}
+
+	t.Run("exact match is refused with the candidates", func(t *testing.T) {
+		mockRepo, service := setup(customer.DuplicateCheckExact)
+		mockRepo.On("FindByNameContaining", ctx, "budi santoso", 500).Return(existing, nil).Once()
+
+		_, err := service.CreateNewCustomer(ctx, " Budi Santoso ", "Jalan Merdeka No. 5", "", uuid.Nil, false)
+
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+		assert.Equal(t, map[string]string{"candidateIds": "3"}, apperrors.Metadata(err))
+		mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+	})
+
+	t.Run("fuzzy match ignores case, punctuation and abbreviations", func(t *testing.T) {
+		mockRepo, service := setup(customer.DuplicateCheckFuzzy)
+		mockRepo.On("FindByNameContaining", ctx, "santoso", 500).Return(existing, nil).Once()
+
+		_, err := service.CreateNewCustomer(ctx, "Santoso, Budi", "Jl. Merdeka Nomor 5", "", uuid.Nil, false)
+
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+		assert.Equal(t, map[string]string{"candidateIds": "3,11"}, apperrors.Metadata(err))
+	})
+
+	t.Run("a different address is created", func(t *testing.T) {
+		mockRepo, service := setup(customer.DuplicateCheckExact)
+		mockRepo.On("FindByNameContaining", ctx, "budi santoso", 500).Return(existing, nil).Once()
+		mockRepo.On("Save", ctx, mock.Anything).Return(nil).Once()
+
+		_, err := service.CreateNewCustomer(ctx, "Budi Santoso", "Jl. Gatot Subroto 1", "", uuid.Nil, false)
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("force skips the check", func(t *testing.T) {
+		mockRepo, service := setup(customer.DuplicateCheckExact)
+		mockRepo.On("Save", ctx, mock.Anything).Return(nil).Once()
+
+		_, err := service.CreateNewCustomer(ctx, "Budi Santoso", "Jalan Merdeka No. 5", "", uuid.Nil, true)
+
+		assert.NoError(t, err)
+		mockRepo.AssertNotCalled(t, "FindByNameContaining", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("lookup failure", func(t *testing.T) {
+		mockRepo, service := setup(customer.DuplicateCheckFuzzy)
+		mockRepo.On("FindByNameContaining", ctx, "santoso", 500).Return(nil, apperrors.ErrDatabase).Once()
+
+		_, err := service.CreateNewCustomer(ctx, "Budi Santoso", "Jalan Merdeka No. 5", "", uuid.Nil, false)
+
+		assert.ErrorIs(t, err, apperrors.ErrDatabase)
+		mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+	})
+}
+
+func TestCustomerServiceResolveCustomerID(t *testing.T) {
+	ctx := context.Background()
+	publicID := uuid.New()
+
+	t.Run("Success", func(t *testing.T) {
+		mockRepo, service := setupTest()
+		mockRepo.On("FindByPublicID", ctx, publicID).Return(&customer.Customer{CustomerID: 4, PublicID: publicID}, nil).Once()
+
+		id, err := service.ResolveCustomerID(ctx, publicID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(4), id)
+	})
+
+	t.Run("Error - Not Found", func(t *testing.T) {
+		mockRepo, service := setupTest()
+		mockRepo.On("FindByPublicID", ctx, publicID).Return(nil, apperrors.ErrNotFound).Once()
+
+		_, err := service.ResolveCustomerID(ctx, publicID)
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+}
+
 func TestNewCustomerService(t *testing.T) {
 	t.Run("Panic on nil repository", func(t *testing.T) {
 		assert.PanicsWithValue(t, "customer repository cannot be nil", func() {
-			customer.NewCustomerService(nil, nil, slog.Default())
+			customer.NewCustomerService(nil, nil, customer.DuplicatePolicy{}, nil, slog.Default())
 		})
 	})
 
 	t.Run("Default logger if none provided", func(t *testing.T) {
 
 		assert.NotPanics(t, func() {
-			_ = customer.NewCustomerService(new(customer.MockCustomerRepository), nil, nil)
+			_ = customer.NewCustomerService(new(customer.MockCustomerRepository), nil, customer.DuplicatePolicy{}, nil, nil)
 		})
 
 	})
 }
+
+func TestCustomerServiceEventTimestamps(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	mockRepo := new(customer.MockCustomerRepository)
+	mockEvent := new(MockEventPublisher)
+	service := customer.NewCustomerService(mockRepo, mockEvent, customer.DuplicatePolicy{}, clock.NewFake(now), slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	mockRepo.On("Save", ctx, mock.Anything).Return(nil)
+	mockRepo.On("FindByID", ctx, int64(7)).Return(&customer.Customer{CustomerID: 7, Name: "Test User", Address: "123 Test St", Active: true}, nil)
+	mockEvent.On("PublishCustomerCreated", ctx, mock.MatchedBy(func(e event.CustomerCreatedEvent) bool {
+		return e.Timestamp.Equal(now)
+	})).Return(nil).Once()
+	mockEvent.On("PublishCustomerUpdated", ctx, mock.MatchedBy(func(e event.CustomerUpdatedEvent) bool {
+		return e.Timestamp.Equal(now)
+	})).Return(nil).Once()
+	mockEvent.On("PublishBatch", ctx, mock.MatchedBy(func(msgs []event.Message) bool {
+		return len(msgs) == 1 && msgs[0].OccurredAt.Equal(now)
+	})).Return(nil).Once()
+
+	_, err := service.CreateNewCustomer(ctx, "Test User", "123 Test St", "", uuid.Nil, false)
+	assert.NoError(t, err)
+	assert.NoError(t, service.UpdateCustomerAddress(ctx, 7, "456 Other St"))
+	mockEvent.AssertExpectations(t)
+}
+
+func TestCustomerServiceGetPreferences(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Success", func(t *testing.T) {
+		mockRepo, service := setupTest()
+		stored := &customer.Preferences{CustomerID: 5, PreferredChannel: customer.ChannelEmail}
+		mockRepo.On("GetPreferences", ctx, int64(5)).Return(stored, nil).Once()
+
+		prefs, err := service.GetPreferences(ctx, 5)
+		assert.NoError(t, err)
+		assert.Equal(t, stored, prefs)
+	})
+
+	t.Run("Error - Not Found", func(t *testing.T) {
+		mockRepo, service := setupTest()
+		mockRepo.On("GetPreferences", ctx, int64(6)).Return(nil, customer.ErrNotFound).Once()
+
+		_, err := service.GetPreferences(ctx, 6)
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+}
+
+func TestCustomerServiceUpdatePreferences(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+
+	newService := func() (*customer.MockCustomerRepository, *MockEventPublisher, customer.CustomerService) {
+		mockRepo := new(customer.MockCustomerRepository)
+		mockEvent := new(MockEventPublisher)
+		service := customer.NewCustomerService(mockRepo, mockEvent, customer.DuplicatePolicy{}, clock.NewFake(now), slog.New(slog.NewTextHandler(io.Discard, nil)))
+		return mockRepo, mockEvent, service
+	}
+
+	t.Run("Success - normalizes and publishes", func(t *testing.T) {
+		mockRepo, mockEvent, service := newService()
+		mockRepo.On("SavePreferences", ctx, mock.MatchedBy(func(p *customer.Preferences) bool {
+			return p.PreferredChannel == "sms" && p.Language == "id-ID"
+		})).Run(func(args mock.Arguments) {
+			args.Get(1).(*customer.Preferences).UpdatedAt = now
+		}).Return(nil).Once()
+		mockEvent.On("PublishBatch", ctx, mock.MatchedBy(func(msgs []event.Message) bool {
+			if len(msgs) != 1 || msgs[0].EntityID != 9 {
+				return false
+			}
+			e, ok := msgs[0].Payload.(event.CustomerPreferencesChangedEvent)
+			return ok && e.EventID != "" && e.PreferredChannel == "sms" && e.MarketingOptOut && e.UpdatedAt.Equal(now)
+		})).Return(nil).Once()
+
+		prefs, err := service.UpdatePreferences(ctx, customer.Preferences{CustomerID: 9, PreferredChannel: " SMS ", Language: "ID-ID", MarketingOptOut: true})
+		assert.NoError(t, err)
+		assert.Equal(t, "sms", prefs.PreferredChannel)
+		assert.Equal(t, "id-ID", prefs.Language)
+		mockRepo.AssertExpectations(t)
+		mockEvent.AssertExpectations(t)
+	})
+
+	t.Run("Error - Invalid Channel", func(t *testing.T) {
+		mockRepo, mockEvent, service := newService()
+		_, err := service.UpdatePreferences(ctx, customer.Preferences{CustomerID: 9, PreferredChannel: "fax"})
+		assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+		mockRepo.AssertNotCalled(t, "SavePreferences", mock.Anything, mock.Anything)
+		mockEvent.AssertNotCalled(t, "PublishBatch", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Error - Invalid Language", func(t *testing.T) {
+		_, _, service := newService()
+		_, err := service.UpdatePreferences(ctx, customer.Preferences{CustomerID: 9, Language: "english"})
+		assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+	})
+
+	t.Run("Error - Not Found", func(t *testing.T) {
+		mockRepo, mockEvent, service := newService()
+		mockRepo.On("SavePreferences", ctx, mock.Anything).Return(customer.ErrNotFound).Once()
+		_, err := service.UpdatePreferences(ctx, customer.Preferences{CustomerID: 404})
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+		mockEvent.AssertNotCalled(t, "PublishBatch", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Success - publish failure does not fail the update", func(t *testing.T) {
+		mockRepo, mockEvent, service := newService()
+		mockRepo.On("SavePreferences", ctx, mock.Anything).Return(nil).Once()
+		mockEvent.On("PublishBatch", ctx, mock.Anything).Return(errors.New("broker down")).Once()
+		_, err := service.UpdatePreferences(ctx, customer.Preferences{CustomerID: 9})
+		assert.NoError(t, err)
+	})
+}
+
+func TestCustomerServiceRequestRiskScore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	publicID := uuid.MustParse("7b2f6a6e-1c4d-4f7e-9a35-0d8e2c1b5f44")
+	externalRef := "CRM-0001"
+	cust := &customer.Customer{CustomerID: 9, PublicID: publicID, Name: "Jane", Address: "1 Main St", ExternalRef: &externalRef, Active: true}
+
+	newService := func() (*MockEventPublisher, customer.CustomerService) {
+		mockEvent := new(MockEventPublisher)
+		return mockEvent, customer.NewCustomerService(new(customer.MockCustomerRepository), mockEvent, customer.DuplicatePolicy{}, clock.NewFake(now), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	}
+
+	t.Run("Success - loan application names the principal", func(t *testing.T) {
+		mockEvent, service := newService()
+		mockEvent.On("PublishBatch", ctx, mock.MatchedBy(func(msgs []event.Message) bool {
+			if len(msgs) != 1 || msgs[0].Type != event.TypeCustomerRiskScoreRequested || msgs[0].EntityID != 9 {
+				return false
+			}
+			e := msgs[0].Payload.(event.CustomerRiskScoreRequestedEvent)
+			return e.EventID != "" && e.PublicID == publicID.String() && *e.ExternalRef == externalRef && e.Name == "Jane" &&
+				e.Reason == "LOAN_APPLICATION" && e.Principal != nil && *e.Principal == 5000000 && e.Timestamp.Equal(now)
+		})).Return(nil).Once()
+
+		service.RequestRiskScore(ctx, cust, customer.RiskScoreLoanApplication, 5000000)
+		mockEvent.AssertExpectations(t)
+	})
+
+	t.Run("Success - new customer has no principal", func(t *testing.T) {
+		mockEvent, service := newService()
+		mockEvent.On("PublishBatch", ctx, mock.MatchedBy(func(msgs []event.Message) bool {
+			e := msgs[0].Payload.(event.CustomerRiskScoreRequestedEvent)
+			return e.Reason == "CUSTOMER_CREATED" && e.Principal == nil
+		})).Return(nil).Once()
+
+		service.RequestRiskScore(ctx, cust, customer.RiskScoreCustomerCreated, 0)
+		mockEvent.AssertExpectations(t)
+	})
+
+	t.Run("Publish failure is only logged", func(t *testing.T) {
+		mockEvent, service := newService()
+		mockEvent.On("PublishBatch", ctx, mock.Anything).Return(errors.New("broker down")).Once()
+
+		assert.NotPanics(t, func() { service.RequestRiskScore(ctx, cust, customer.RiskScoreCustomerCreated, 0) })
+		mockEvent.AssertExpectations(t)
+	})
+}
+
+func TestCustomerServiceUpdateRiskScore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+
+	newService := func() (*customer.MockCustomerRepository, customer.CustomerService) {
+		mockRepo := new(customer.MockCustomerRepository)
+		return mockRepo, customer.NewCustomerService(mockRepo, new(MockEventPublisher), customer.DuplicatePolicy{}, clock.NewFake(now), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	}
+
+	t.Run("Success - normalizes and returns the customer", func(t *testing.T) {
+		mockRepo, service := newService()
+		mockRepo.On("SetRiskScore", ctx, &customer.RiskAssessment{CustomerID: 9, Score: 712, Grade: "B+", ScoredAt: now}).Return(nil).Once()
+		score, grade := 712, "B+"
+		stored := &customer.Customer{CustomerID: 9, RiskScore: &score, RiskGrade: &grade, RiskScoredAt: &now}
+		mockRepo.On("FindByID", ctx, int64(9)).Return(stored, nil).Once()
+
+		cust, err := service.UpdateRiskScore(ctx, customer.RiskAssessment{CustomerID: 9, Score: 712, Grade: " b+ "})
+		assert.NoError(t, err)
+		assert.Equal(t, stored, cust)
+		assert.True(t, cust.Scored())
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Error - Invalid assessment", func(t *testing.T) {
+		for name, a := range map[string]customer.RiskAssessment{
+			"score above range": {CustomerID: 9, Score: 1001, Grade: "A"},
+			"negative score":    {CustomerID: 9, Score: -1, Grade: "A"},
+			"empty grade":       {CustomerID: 9, Score: 700, Grade: " "},
+			"grade too long":    {CustomerID: 9, Score: 700, Grade: "EXCELLENT"},
+			"grade with spaces": {CustomerID: 9, Score: 700, Grade: "A B"},
+			"scored in future":  {CustomerID: 9, Score: 700, Grade: "A", ScoredAt: now.Add(time.Minute)},
+		} {
+			t.Run(name, func(t *testing.T) {
+				mockRepo, service := newService()
+				_, err := service.UpdateRiskScore(ctx, a)
+				assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+				mockRepo.AssertNotCalled(t, "SetRiskScore", mock.Anything, mock.Anything)
+			})
+		}
+	})
+
+	t.Run("Error - Not Found", func(t *testing.T) {
+		mockRepo, service := newService()
+		mockRepo.On("SetRiskScore", ctx, mock.Anything).Return(customer.ErrNotFound).Once()
+		_, err := service.UpdateRiskScore(ctx, customer.RiskAssessment{CustomerID: 404, Score: 500, Grade: "C"})
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+}
+
+func TestCustomerServiceMergeCustomers(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 5, 2, 10, 0, 0, 0, time.UTC)
+	loanID := int64(5)
+
+	newService := func() (*customer.MockCustomerRepository, *MockEventPublisher, customer.CustomerService) {
+		mockRepo := new(customer.MockCustomerRepository)
+		mockEvent := new(MockEventPublisher)
+		return mockRepo, mockEvent, customer.NewCustomerService(mockRepo, mockEvent, customer.DuplicatePolicy{}, clock.NewFake(now), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	}
+	newMerge := func() *customer.Merge {
+		m := customer.NewMerge(&customer.Customer{CustomerID: 1, Active: true}, &customer.Customer{CustomerID: 2, Active: true, LoanID: &loanID}, now)
+		m.Moved = customer.MergedRecords{Notes: 2, Events: 4}
+		return m
+	}
+
+	t.Run("Success - publishes the merge and both customers", func(t *testing.T) {
+		mockRepo, mockEvent, service := newService()
+		merge := newMerge()
+		mockRepo.On("MergeCustomers", ctx, int64(1), int64(2)).Return(merge, nil).Once()
+		mockEvent.On("PublishBatch", ctx, mock.MatchedBy(func(msgs []event.Message) bool {
+			if len(msgs) != 3 || msgs[0].Type != event.TypeCustomerMerged || msgs[0].EntityID != 1 {
+				return false
+			}
+			e := msgs[0].Payload.(event.CustomerMergedEvent)
+			target := msgs[1].Payload.(event.CustomerUpdatedEvent).Payload
+			source := msgs[2].Payload.(event.CustomerUpdatedEvent).Payload
+			return e.TargetID == 1 && e.SourceID == 2 && *e.LoanID == loanID && e.MergedAt.Equal(now) &&
+				*target.LoanID == loanID && source.CustomerID == 2 && !source.Active && source.LoanID == nil
+		})).Return(nil).Once()
+
+		got, err := service.MergeCustomers(ctx, 1, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, merge, got)
+		mockRepo.AssertExpectations(t)
+		mockEvent.AssertExpectations(t)
+	})
+
+	t.Run("Error - merged into itself", func(t *testing.T) {
+		mockRepo, _, service := newService()
+		_, err := service.MergeCustomers(ctx, 1, 1)
+		assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+		mockRepo.AssertNotCalled(t, "MergeCustomers", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Error - refused merge publishes nothing", func(t *testing.T) {
+		mockRepo, mockEvent, service := newService()
+		mockRepo.On("MergeCustomers", ctx, int64(1), int64(2)).Return(nil, fmt.Errorf("%w: both hold a loan", apperrors.ErrConflict)).Once()
+		_, err := service.MergeCustomers(ctx, 1, 2)
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+		mockEvent.AssertNotCalled(t, "PublishBatch", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Success - publish failure does not fail the merge", func(t *testing.T) {
+		mockRepo, mockEvent, service := newService()
+		mockRepo.On("MergeCustomers", ctx, int64(1), int64(2)).Return(newMerge(), nil).Once()
+		mockEvent.On("PublishBatch", ctx, mock.Anything).Return(errors.New("broker down")).Once()
+		_, err := service.MergeCustomers(ctx, 1, 2)
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,296 @@
+package directdebit
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FormatCSV     = "csv"
+	FormatPain008 = "pain.008"
+
+	pain008Namespace = "urn:iso:std:iso:20022:tech:xsd:pain.008.001.02"
+)
+
+// Creditor is the lender as it appears in exported bank files.
+type Creditor struct {
+	Name          string
+	AccountNumber string
+	BankCode      string
+	// SchemeID is the creditor identifier assigned by the direct-debit
+	// scheme.
+	SchemeID string
+}
+
+// Extension is the file name extension for format.
+func Extension(format string) string {
+	if format == FormatPain008 {
+		return ".xml"
+	}
+	return ".csv"
+}
+
+func validateFormat(format string) error {
+	if format != FormatCSV && format != FormatPain008 {
+		return fmt.Errorf("%w: unknown bank file format %q, use %q or %q", apperrors.ErrInvalidArgument, format, FormatCSV, FormatPain008)
+	}
+	return nil
+}
+
+// fileWriter formats amounts for the configured currency.
+type fileWriter struct {
+	creditor Creditor
+	currency string
+	digits   int
+}
+
+func (f fileWriter) amount(v float64) string {
+	return strconv.FormatFloat(v, 'f', f.digits, 64)
+}
+
+func remittance(in Instruction) string {
+	return fmt.Sprintf("Loan %d installment %d", in.LoanID, in.WeekNumber)
+}
+
+var csvExportHeader = []string{
+	"end_to_end_id", "mandate_reference", "mandate_signed_at", "account_holder", "account_number", "bank_code",
+	"amount", "currency", "collection_date", "remittance",
+}
+
+func (f fileWriter) writeCSV(w io.Writer, instructions []Instruction) error {
+	out := csv.NewWriter(w)
+	if err := out.Write(csvExportHeader); err != nil {
+		return err
+	}
+	for _, in := range instructions {
+		record := []string{
+			in.EndToEndID,
+			in.Mandate.Reference,
+			in.Mandate.SignedAt.Format(time.DateOnly),
+			in.Mandate.AccountHolder,
+			in.Mandate.AccountNumber,
+			in.Mandate.BankCode,
+			f.amount(in.Amount),
+			f.currency,
+			in.CollectionDate.Format(time.DateOnly),
+			remittance(in),
+		}
+		if err := out.Write(record); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// The pain.008 types cover the subset of CustomerDirectDebitInitiationV02
+// the export fills in. Accounts and banks are identified with the generic
+// Othr elements since not every bank uses IBAN and BIC.
+type pain008Document struct {
+	XMLName xml.Name          `xml:"Document"`
+	Xmlns   string            `xml:"xmlns,attr"`
+	Body    pain008Initiation `xml:"CstmrDrctDbtInitn"`
+}
+
+type pain008Initiation struct {
+	GroupHeader pain008GroupHeader  `xml:"GrpHdr"`
+	Payments    []pain008PaymentInf `xml:"PmtInf"`
+}
+
+type pain008GroupHeader struct {
+	MsgID                string      `xml:"MsgId"`
+	CreationDateTime     string      `xml:"CreDtTm"`
+	NumberOfTransactions int         `xml:"NbOfTxs"`
+	ControlSum           string      `xml:"CtrlSum"`
+	InitiatingParty      pain008Name `xml:"InitgPty"`
+}
+
+type pain008Name struct {
+	Name string `xml:"Nm"`
+}
+
+type pain008Other struct {
+	ID string `xml:"Othr>Id"`
+}
+
+type pain008Account struct {
+	ID pain008Other `xml:"Id"`
+}
+
+type pain008Agent struct {
+	FinancialInstitution pain008Other `xml:"FinInstnId"`
+}
+
+type pain008PaymentInf struct {
+	PaymentInfoID        string         `xml:"PmtInfId"`
+	PaymentMethod        string         `xml:"PmtMtd"`
+	NumberOfTransactions int            `xml:"NbOfTxs"`
+	ControlSum           string         `xml:"CtrlSum"`
+	LocalInstrument      string         `xml:"PmtTpInf>LclInstrm>Cd"`
+	SequenceType         string         `xml:"PmtTpInf>SeqTp"`
+	RequestedCollection  string         `xml:"ReqdColltnDt"`
+	Creditor             pain008Name    `xml:"Cdtr"`
+	CreditorAccount      pain008Account `xml:"CdtrAcct"`
+	CreditorAgent        pain008Agent   `xml:"CdtrAgt"`
+	CreditorSchemeID     string         `xml:"CdtrSchmeId>Id>PrvtId>Othr>Id"`
+	Transactions         []pain008Debit `xml:"DrctDbtTxInf"`
+}
+
+type pain008Debit struct {
+	EndToEndID      string         `xml:"PmtId>EndToEndId"`
+	Amount          pain008Amount  `xml:"InstdAmt"`
+	MandateID       string         `xml:"DrctDbtTx>MndtRltdInf>MndtId"`
+	MandateSignedOn string         `xml:"DrctDbtTx>MndtRltdInf>DtOfSgntr"`
+	DebtorAgent     pain008Agent   `xml:"DbtrAgt"`
+	Debtor          pain008Name    `xml:"Dbtr"`
+	DebtorAccount   pain008Account `xml:"DbtrAcct"`
+	Remittance      string         `xml:"RmtInf>Ustrd"`
+}
+
+type pain008Amount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+// writePain008 groups the instructions into one payment information block
+// per collection date, as banks require a single date per block.
+func (f fileWriter) writePain008(w io.Writer, ref string, createdAt time.Time, instructions []Instruction) error {
+	doc := pain008Document{Xmlns: pain008Namespace}
+	header := &doc.Body.GroupHeader
+	header.MsgID = ref
+	header.CreationDateTime = createdAt.UTC().Format("2006-01-02T15:04:05")
+	header.InitiatingParty.Name = f.creditor.Name
+
+	var total float64
+	var sums []float64
+	blocks := map[string]int{}
+	for _, in := range instructions {
+		date := in.CollectionDate.Format(time.DateOnly)
+		i, ok := blocks[date]
+		if !ok {
+			i = len(doc.Body.Payments)
+			blocks[date] = i
+			doc.Body.Payments = append(doc.Body.Payments, pain008PaymentInf{
+				PaymentInfoID:       fmt.Sprintf("%s-%d", ref, i+1),
+				PaymentMethod:       "DD",
+				LocalInstrument:     "CORE",
+				SequenceType:        "RCUR",
+				RequestedCollection: date,
+				Creditor:            pain008Name{Name: f.creditor.Name},
+				CreditorAccount:     pain008Account{ID: pain008Other{ID: f.creditor.AccountNumber}},
+				CreditorAgent:       pain008Agent{FinancialInstitution: pain008Other{ID: f.creditor.BankCode}},
+				CreditorSchemeID:    f.creditor.SchemeID,
+			})
+			sums = append(sums, 0)
+		}
+		block := &doc.Body.Payments[i]
+		block.Transactions = append(block.Transactions, pain008Debit{
+			EndToEndID:      in.EndToEndID,
+			Amount:          pain008Amount{Currency: f.currency, Value: f.amount(in.Amount)},
+			MandateID:       in.Mandate.Reference,
+			MandateSignedOn: in.Mandate.SignedAt.Format(time.DateOnly),
+			DebtorAgent:     pain008Agent{FinancialInstitution: pain008Other{ID: in.Mandate.BankCode}},
+			Debtor:          pain008Name{Name: in.Mandate.AccountHolder},
+			DebtorAccount:   pain008Account{ID: pain008Other{ID: in.Mandate.AccountNumber}},
+			Remittance:      remittance(in),
+		})
+		sums[i] += in.Amount
+		total += in.Amount
+	}
+
+	for i := range doc.Body.Payments {
+		block := &doc.Body.Payments[i]
+		block.NumberOfTransactions = len(block.Transactions)
+		block.ControlSum = f.amount(sums[i])
+	}
+	header.NumberOfTransactions = len(instructions)
+	header.ControlSum = f.amount(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// ResultRow is one line of a bank result file.
+type ResultRow struct {
+	Line       int
+	EndToEndID string
+	Collected  bool
+	Reason     string
+}
+
+// ReadResults parses a result file: CSV with a header row naming the
+// end_to_end_id, status and optional reason columns. Status is COLLECTED or
+// FAILED, or the ISO 20022 codes ACSC and RJCT.
+func ReadResults(r io.Reader) ([]ResultRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, resultFileError(err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\uFEFF")))] = i
+	}
+	for _, required := range []string{"end_to_end_id", "status"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("%w: result file header is missing the %q column", apperrors.ErrInvalidArgument, required)
+		}
+	}
+	field := func(record []string, column string) string {
+		if i, ok := columns[column]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var rows []ResultRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, resultFileError(err)
+		}
+		line, _ := reader.FieldPos(0)
+		row := ResultRow{Line: line, EndToEndID: field(record, "end_to_end_id"), Reason: field(record, "reason")}
+		switch strings.ToUpper(field(record, "status")) {
+		case "COLLECTED", "ACSC":
+			row.Collected = true
+		case "FAILED", "RJCT":
+		default:
+			return nil, fmt.Errorf("%w: unknown status %q on line %d, use COLLECTED or FAILED",
+				apperrors.ErrInvalidArgument, field(record, "status"), line)
+		}
+		rows = append(rows, row)
+	}
+}
+
+func resultFileError(err error) error {
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return fmt.Errorf("%w: malformed result file: %v", apperrors.ErrInvalidArgument, err)
+	}
+	return err
+}
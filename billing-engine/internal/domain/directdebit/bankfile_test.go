@@ -0,0 +1,90 @@
+package directdebit
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportBatchPain008(t *testing.T) {
+	ctx := context.Background()
+	repo := new(MockRepository)
+	instructions := pendingInstructions()
+	instructions = append(instructions, Instruction{
+		ID: 3, MandateID: 3, LoanID: 6, WeekNumber: 1, EndToEndID: "E2E3", Amount: 90, CollectionDate: day("2025-01-08"), Mandate: testMandate,
+	})
+	repo.On("ListInstructions", ctx, InstructionPending).Return(instructions, nil).Once()
+	repo.On("MarkExported", ctx, []int64{1, 2, 3}, mock.Anything).Return(int64(3), nil).Once()
+	var buf bytes.Buffer
+
+	batch, err := newTestService(repo, new(MockPaymentPoster), FormatPain008).ExportBatch(ctx, &buf)
+
+	require.NoError(t, err)
+	assert.Equal(t, FormatPain008, batch.Format)
+	assert.True(t, strings.HasPrefix(buf.String(), xml.Header))
+	assert.Contains(t, buf.String(), `<Document xmlns="`+pain008Namespace+`">`)
+
+	var doc pain008Document
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &doc))
+	header := doc.Body.GroupHeader
+	assert.Equal(t, batch.Ref, header.MsgID)
+	assert.Equal(t, "2025-01-06T06:00:00", header.CreationDateTime)
+	assert.Equal(t, 3, header.NumberOfTransactions)
+	assert.Equal(t, "310.50", header.ControlSum)
+
+	require.Len(t, doc.Body.Payments, 2, "one block per collection date")
+	first := doc.Body.Payments[0]
+	assert.Equal(t, "2025-01-08", first.RequestedCollection)
+	assert.Equal(t, 2, first.NumberOfTransactions)
+	assert.Equal(t, "200.00", first.ControlSum)
+	assert.Equal(t, "ID00ZZZ123", first.CreditorSchemeID)
+	assert.Equal(t, "1234567890", first.CreditorAccount.ID.ID)
+	assert.Equal(t, pain008Debit{
+		EndToEndID:      "E2E1",
+		Amount:          pain008Amount{Currency: "IDR", Value: "110.00"},
+		MandateID:       "MNDT-7",
+		MandateSignedOn: "2024-12-20",
+		DebtorAgent:     pain008Agent{FinancialInstitution: pain008Other{ID: "BANKIDJB"}},
+		Debtor:          pain008Name{Name: "Jane Doe"},
+		DebtorAccount:   pain008Account{ID: pain008Other{ID: "9876543210"}},
+		Remittance:      "Loan 5 installment 1",
+	}, first.Transactions[0])
+	assert.Equal(t, "2025-01-13", doc.Body.Payments[1].RequestedCollection)
+}
+
+func TestReadResults(t *testing.T) {
+	t.Run("maps columns by name and accepts ISO status codes", func(t *testing.T) {
+		rows, err := ReadResults(strings.NewReader("\uFEFFStatus,End_To_End_ID,Reason\nCOLLECTED,E2E1,\nrjct,E2E2,AM04 insufficient funds\nACSC,E2E3\n"))
+
+		require.NoError(t, err)
+		assert.Equal(t, []ResultRow{
+			{Line: 2, EndToEndID: "E2E1", Collected: true},
+			{Line: 3, EndToEndID: "E2E2", Reason: "AM04 insufficient funds"},
+			{Line: 4, EndToEndID: "E2E3", Collected: true},
+		}, rows)
+	})
+
+	t.Run("rejects a missing column", func(t *testing.T) {
+		_, err := ReadResults(strings.NewReader("end_to_end_id,reason\nE2E1,\n"))
+		assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+		assert.ErrorContains(t, err, `"status"`)
+	})
+
+	t.Run("rejects an unknown status", func(t *testing.T) {
+		_, err := ReadResults(strings.NewReader("end_to_end_id,status\nE2E1,PENDING\n"))
+		assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+		assert.ErrorContains(t, err, "line 2")
+	})
+
+	t.Run("rejects malformed CSV", func(t *testing.T) {
+		_, err := ReadResults(strings.NewReader("end_to_end_id,status\n\"E2E1,COLLECTED\n"))
+		assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+	})
+}
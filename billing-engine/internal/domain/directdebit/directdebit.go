@@ -0,0 +1,134 @@
+package directdebit
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type MandateStatus string
+
+const (
+	MandateActive    MandateStatus = "ACTIVE"
+	MandateCancelled MandateStatus = "CANCELLED"
+)
+
+type InstructionStatus string
+
+const (
+	// InstructionPending is generated but not yet sent to the bank.
+	InstructionPending InstructionStatus = "PENDING"
+	// InstructionExported went out in a bank file and awaits its result.
+	InstructionExported InstructionStatus = "EXPORTED"
+	// InstructionCollected was collected and posted as a loan payment.
+	InstructionCollected InstructionStatus = "COLLECTED"
+	// InstructionFailed was rejected or returned by the bank. The
+	// installment is picked up again by the next run.
+	InstructionFailed InstructionStatus = "FAILED"
+	// InstructionUnapplied was collected by the bank but could not be posted
+	// to the loan and needs manual reconciliation.
+	InstructionUnapplied InstructionStatus = "UNAPPLIED"
+)
+
+const (
+	// MaxReferenceLength is the limit ISO 20022 puts on mandate, message and
+	// end-to-end identifiers.
+	MaxReferenceLength     = 35
+	MaxAccountHolderLength = 140
+	MaxAccountNumberLength = 34
+	MaxBankCodeLength      = 11
+)
+
+// Mandate is a customer's authorisation to collect installments from their
+// bank account. A customer holds at most one active mandate.
+type Mandate struct {
+	ID            int64
+	CustomerID    int64
+	Reference     string
+	AccountHolder string
+	AccountNumber string
+	BankCode      string
+	Status        MandateStatus
+	SignedAt      time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// Installment is an unpaid schedule entry of a loan whose customer holds an
+// active mandate. Amount is what is still due on it.
+type Installment struct {
+	Mandate    Mandate
+	LoanID     int64
+	ScheduleID int64
+	WeekNumber int
+	DueDate    time.Time
+	Amount     float64
+}
+
+// Instruction asks the bank to collect one installment under a mandate.
+// Mandate is filled in when instructions are listed for export.
+type Instruction struct {
+	ID             int64
+	MandateID      int64
+	LoanID         int64
+	ScheduleID     int64
+	WeekNumber     int
+	EndToEndID     string
+	Amount         float64
+	CollectionDate time.Time
+	Status         InstructionStatus
+	BatchRef       string
+	FailureReason  string
+	Mandate        Mandate
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// Batch describes one exported bank file.
+type Batch struct {
+	Ref          string
+	Format       string
+	Instructions int
+	Total        float64
+}
+
+func (m *Mandate) normalize() {
+	m.Reference = strings.TrimSpace(m.Reference)
+	m.AccountHolder = strings.TrimSpace(m.AccountHolder)
+	m.AccountNumber = strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(m.AccountNumber), " ", ""))
+	m.BankCode = strings.ToUpper(strings.TrimSpace(m.BankCode))
+}
+
+func (m *Mandate) validate() error {
+	switch {
+	case m.CustomerID <= 0:
+		return fmt.Errorf("customer ID must be a positive number")
+	case m.AccountHolder == "":
+		return fmt.Errorf("account holder cannot be empty")
+	case utf8.RuneCountInString(m.AccountHolder) > MaxAccountHolderLength:
+		return fmt.Errorf("account holder cannot exceed %d characters", MaxAccountHolderLength)
+	case m.AccountNumber == "":
+		return fmt.Errorf("account number cannot be empty")
+	case len(m.AccountNumber) > MaxAccountNumberLength || !isAlphanumeric(m.AccountNumber):
+		return fmt.Errorf("account number must be up to %d letters and digits", MaxAccountNumberLength)
+	case m.BankCode == "":
+		return fmt.Errorf("bank code cannot be empty")
+	case len(m.BankCode) > MaxBankCodeLength || !isAlphanumeric(m.BankCode):
+		return fmt.Errorf("bank code must be up to %d letters and digits", MaxBankCodeLength)
+	case len(m.Reference) > MaxReferenceLength:
+		return fmt.Errorf("reference cannot exceed %d characters", MaxReferenceLength)
+	case m.SignedAt.IsZero():
+		return fmt.Errorf("signature date is required")
+	}
+	return nil
+}
+
+func isAlphanumeric(s string) bool {
+	for _, r := range s {
+		if (r < '0' || r > '9') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
+}
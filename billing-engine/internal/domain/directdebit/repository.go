@@ -0,0 +1,44 @@
+package directdebit
+
+import (
+	"context"
+	"time"
+)
+
+type Repository interface {
+	// CreateMandate stores an active mandate. It returns ErrNotFound for an
+	// unknown customer, ErrConflict when the customer already holds an
+	// active mandate and ErrAlreadyExists for a reference in use.
+	CreateMandate(ctx context.Context, mandate *Mandate) error
+
+	ListMandates(ctx context.Context, customerID int64) ([]Mandate, error)
+
+	// CancelMandate cancels the mandate only when it belongs to customerID
+	// and is still active.
+	CancelMandate(ctx context.Context, customerID, mandateID int64) error
+
+	// ListUncoveredInstallments returns the unpaid installments due on or
+	// before through of loans whose customer holds an active mandate, leaving
+	// out those a pending, exported or collected instruction already covers
+	// and those of loans on hold.
+	ListUncoveredInstallments(ctx context.Context, through time.Time) ([]Installment, error)
+
+	// CreateInstruction returns ErrAlreadyExists when another open
+	// instruction covers the same installment.
+	CreateInstruction(ctx context.Context, instruction *Instruction) error
+
+	// ListInstructions returns the instructions in status together with
+	// their mandates, by collection date and ID.
+	ListInstructions(ctx context.Context, status InstructionStatus) ([]Instruction, error)
+
+	// MarkExported moves the given pending instructions to exported under
+	// batchRef and reports how many it moved.
+	MarkExported(ctx context.Context, ids []int64, batchRef string) (int64, error)
+
+	GetInstructionByEndToEndID(ctx context.Context, endToEndID string) (*Instruction, error)
+
+	// TransitionInstruction changes the status of the instruction only while
+	// it is still in from, and returns ErrConflict otherwise. This keeps two
+	// uploads of the same result file from posting a payment twice.
+	TransitionInstruction(ctx context.Context, id int64, from, to InstructionStatus, reason string) error
+}
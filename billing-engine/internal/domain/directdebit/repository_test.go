@@ -0,0 +1,66 @@
+package directdebit
+
+import (
+	"billing-engine/internal/domain/loan"
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) CreateMandate(ctx context.Context, mandate *Mandate) error {
+	return m.Called(ctx, mandate).Error(0)
+}
+
+func (m *MockRepository) ListMandates(ctx context.Context, customerID int64) ([]Mandate, error) {
+	args := m.Called(ctx, customerID)
+	mandates, _ := args.Get(0).([]Mandate)
+	return mandates, args.Error(1)
+}
+
+func (m *MockRepository) CancelMandate(ctx context.Context, customerID, mandateID int64) error {
+	return m.Called(ctx, customerID, mandateID).Error(0)
+}
+
+func (m *MockRepository) ListUncoveredInstallments(ctx context.Context, through time.Time) ([]Installment, error) {
+	args := m.Called(ctx, through)
+	installments, _ := args.Get(0).([]Installment)
+	return installments, args.Error(1)
+}
+
+func (m *MockRepository) CreateInstruction(ctx context.Context, instruction *Instruction) error {
+	return m.Called(ctx, instruction).Error(0)
+}
+
+func (m *MockRepository) ListInstructions(ctx context.Context, status InstructionStatus) ([]Instruction, error) {
+	args := m.Called(ctx, status)
+	instructions, _ := args.Get(0).([]Instruction)
+	return instructions, args.Error(1)
+}
+
+func (m *MockRepository) MarkExported(ctx context.Context, ids []int64, batchRef string) (int64, error) {
+	args := m.Called(ctx, ids, batchRef)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepository) GetInstructionByEndToEndID(ctx context.Context, endToEndID string) (*Instruction, error) {
+	args := m.Called(ctx, endToEndID)
+	instruction, _ := args.Get(0).(*Instruction)
+	return instruction, args.Error(1)
+}
+
+func (m *MockRepository) TransitionInstruction(ctx context.Context, id int64, from, to InstructionStatus, reason string) error {
+	return m.Called(ctx, id, from, to, reason).Error(0)
+}
+
+type MockPaymentPoster struct {
+	mock.Mock
+}
+
+func (m *MockPaymentPoster) MakePayment(ctx context.Context, loanID int64, amount loan.Money, details loan.PaymentDetails) error {
+	return m.Called(ctx, loanID, amount, details).Error(0)
+}
@@ -0,0 +1,357 @@
+package directdebit
+
+import (
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ResultStatusCollected = "collected"
+	ResultStatusFailed    = "failed"
+	ResultStatusUnapplied = "unapplied"
+	ResultStatusSkipped   = "skipped"
+
+	// DefaultHorizonDays is how far ahead a run looks for installments: a
+	// weekly run covers everything due before the next one.
+	DefaultHorizonDays = 7
+
+	// resultProgressRows is how often ProcessResults reports progress; every
+	// row posts a payment, so a report per row would only add lock traffic.
+	resultProgressRows = 50
+)
+
+type Service interface {
+	CreateMandate(ctx context.Context, mandate *Mandate) (*Mandate, error)
+	ListMandates(ctx context.Context, customerID int64) ([]Mandate, error)
+	CancelMandate(ctx context.Context, customerID, mandateID int64) error
+
+	// GenerateInstructions creates a pending instruction for every unpaid
+	// installment due within the horizon of asOf that has none yet, and
+	// returns how many it created.
+	GenerateInstructions(ctx context.Context, asOf time.Time) (int, error)
+
+	// ExportBatch writes all pending instructions to w in the configured
+	// format and marks them exported. It writes nothing and returns a batch
+	// of zero instructions when none are pending. On error the caller must
+	// discard whatever was written.
+	ExportBatch(ctx context.Context, w io.Writer) (*Batch, error)
+
+	// ProcessResults posts a payment for every collected instruction and
+	// marks failed ones, so the next run retries their installments. It
+	// reports the rows done so far through progress, when not nil.
+	ProcessResults(ctx context.Context, rows []ResultRow, progress func(processed int)) *ResultReport
+}
+
+// PaymentPoster posts collected amounts; loan.LoanService implements it.
+type PaymentPoster interface {
+	MakePayment(ctx context.Context, loanID int64, amount loan.Money, details loan.PaymentDetails) error
+}
+
+// Config sets the bank file the service exports and when collections are
+// requested.
+type Config struct {
+	Format string
+	// LeadDays is the notice the bank needs between receiving a file and
+	// collecting. Installments due sooner are collected LeadDays after the
+	// run.
+	LeadDays    int
+	HorizonDays int
+	Creditor    Creditor
+	Currency    string
+	// MinorUnitDigits formats amounts in the bank file.
+	MinorUnitDigits int
+}
+
+type ResultOutcome struct {
+	Line       int
+	EndToEndID string
+	Status     string
+	LoanID     int64
+	Error      string
+}
+
+type ResultReport struct {
+	Total     int
+	Collected int
+	Failed    int
+	Unapplied int
+	Skipped   int
+	Results   []ResultOutcome
+}
+
+var _ Service = (*service)(nil)
+
+type service struct {
+	repo     Repository
+	payments PaymentPoster
+	cfg      Config
+	clock    clock.Clock
+	logger   *slog.Logger
+}
+
+// Validate checks the configured values and fills in defaults.
+func (c *Config) Validate() error {
+	if c.Format == "" {
+		c.Format = FormatCSV
+	}
+	if err := validateFormat(c.Format); err != nil {
+		return err
+	}
+	if c.LeadDays < 0 {
+		return fmt.Errorf("%w: lead days must not be negative", apperrors.ErrInvalidArgument)
+	}
+	if c.HorizonDays <= 0 {
+		c.HorizonDays = DefaultHorizonDays
+	}
+	return nil
+}
+
+// NewService wires the direct-debit service. cfg must have passed Validate;
+// the clock stamps exported files and nil means the wall clock.
+func NewService(repo Repository, payments PaymentPoster, cfg Config, clk clock.Clock, logger *slog.Logger) Service {
+	if repo == nil || payments == nil {
+		panic("direct-debit repository and payment poster cannot be nil")
+	}
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+		logger.Warn("Warning: No logger provided to directdebit.NewService, using default stderr handler")
+	}
+	return &service{
+		repo:     repo,
+		payments: payments,
+		cfg:      cfg,
+		clock:    clock.OrSystem(clk),
+		logger:   logger.With(slog.String("component", "directDebitService")),
+	}
+}
+
+func (s *service) CreateMandate(ctx context.Context, mandate *Mandate) (*Mandate, error) {
+	if mandate == nil {
+		return nil, fmt.Errorf("%w: mandate cannot be nil", apperrors.ErrInvalidArgument)
+	}
+	m := *mandate
+	m.normalize()
+	if m.Reference == "" {
+		m.Reference = "MNDT-" + strings.ToUpper(randomHex(8))
+	}
+	m.Status = MandateActive
+	if err := m.validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err)
+	}
+
+	if err := s.repo.CreateMandate(ctx, &m); err != nil {
+		s.logger.ErrorContext(ctx, "Failed to create mandate", slog.Int64("customerID", m.CustomerID), slog.Any("error", err))
+		return nil, fmt.Errorf("failed to create mandate for customer %d: %w", m.CustomerID, err)
+	}
+	s.logger.InfoContext(ctx, "Mandate created", slog.Int64("customerID", m.CustomerID), slog.Int64("mandateID", m.ID))
+	return &m, nil
+}
+
+func (s *service) ListMandates(ctx context.Context, customerID int64) ([]Mandate, error) {
+	if customerID <= 0 {
+		return nil, fmt.Errorf("%w: customer ID must be a positive number", apperrors.ErrInvalidArgument)
+	}
+	mandates, err := s.repo.ListMandates(ctx, customerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list mandates of customer %d: %w", customerID, err)
+	}
+	return mandates, nil
+}
+
+func (s *service) CancelMandate(ctx context.Context, customerID, mandateID int64) error {
+	if customerID <= 0 || mandateID <= 0 {
+		return fmt.Errorf("%w: customer and mandate IDs must be positive numbers", apperrors.ErrInvalidArgument)
+	}
+	if err := s.repo.CancelMandate(ctx, customerID, mandateID); err != nil {
+		return fmt.Errorf("failed to cancel mandate %d: %w", mandateID, err)
+	}
+	s.logger.InfoContext(ctx, "Mandate cancelled", slog.Int64("customerID", customerID), slog.Int64("mandateID", mandateID))
+	return nil
+}
+
+func (s *service) GenerateInstructions(ctx context.Context, asOf time.Time) (int, error) {
+	today := truncateDay(asOf)
+	earliest := today.AddDate(0, 0, s.cfg.LeadDays)
+	installments, err := s.repo.ListUncoveredInstallments(ctx, today.AddDate(0, 0, s.cfg.HorizonDays))
+	if err != nil {
+		return 0, fmt.Errorf("failed to list installments to collect: %w", err)
+	}
+
+	created := 0
+	for _, inst := range installments {
+		if inst.Amount <= 0 {
+			continue
+		}
+		collection := truncateDay(inst.DueDate)
+		if collection.Before(earliest) {
+			collection = earliest
+		}
+		instruction := &Instruction{
+			MandateID:      inst.Mandate.ID,
+			LoanID:         inst.LoanID,
+			ScheduleID:     inst.ScheduleID,
+			WeekNumber:     inst.WeekNumber,
+			EndToEndID:     strings.ReplaceAll(uuid.NewString(), "-", ""),
+			Amount:         inst.Amount,
+			CollectionDate: collection,
+			Status:         InstructionPending,
+		}
+		err := s.repo.CreateInstruction(ctx, instruction)
+		if errors.Is(err, apperrors.ErrAlreadyExists) {
+			// A concurrent run covered the installment first.
+			continue
+		}
+		if err != nil {
+			return created, fmt.Errorf("failed to create instruction for loan %d installment %d: %w", inst.LoanID, inst.WeekNumber, err)
+		}
+		created++
+	}
+	s.logger.InfoContext(ctx, "Direct-debit instructions generated", slog.Int("created", created), slog.String("asOf", today.Format(time.DateOnly)))
+	return created, nil
+}
+
+func (s *service) ExportBatch(ctx context.Context, w io.Writer) (*Batch, error) {
+	pending, err := s.repo.ListInstructions(ctx, InstructionPending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending instructions: %w", err)
+	}
+	now := s.clock.Now()
+	batch := &Batch{Ref: fmt.Sprintf("DD-%s-%s", now.UTC().Format("20060102"), strings.ToUpper(randomHex(4))), Format: s.cfg.Format}
+	if len(pending) == 0 {
+		return batch, nil
+	}
+
+	// The file is written before the instructions are marked, so a caller
+	// that discards the file on error leaves them pending for the next run.
+	writer := fileWriter{creditor: s.cfg.Creditor, currency: s.cfg.Currency, digits: s.cfg.MinorUnitDigits}
+	if s.cfg.Format == FormatPain008 {
+		err = writer.writePain008(w, batch.Ref, now, pending)
+	} else {
+		err = writer.writeCSV(w, pending)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to write %s bank file %s: %w", s.cfg.Format, batch.Ref, err)
+	}
+
+	ids := make([]int64, len(pending))
+	for i, in := range pending {
+		ids[i] = in.ID
+		batch.Total += in.Amount
+	}
+	marked, err := s.repo.MarkExported(ctx, ids, batch.Ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark batch %s exported: %w", batch.Ref, err)
+	}
+	if marked != int64(len(ids)) {
+		return nil, fmt.Errorf("%w: %d of %d instructions of batch %s were exported concurrently",
+			apperrors.ErrConflict, int64(len(ids))-marked, len(ids), batch.Ref)
+	}
+
+	batch.Instructions = len(pending)
+	s.logger.InfoContext(ctx, "Direct-debit batch exported", slog.String("batch", batch.Ref), slog.Int("instructions", batch.Instructions))
+	return batch, nil
+}
+
+func (s *service) ProcessResults(ctx context.Context, rows []ResultRow, progress func(processed int)) *ResultReport {
+	report := &ResultReport{Total: len(rows), Results: make([]ResultOutcome, len(rows))}
+	for i, row := range rows {
+		if progress != nil && i > 0 && i%resultProgressRows == 0 {
+			progress(i)
+		}
+		outcome := &report.Results[i]
+		outcome.Line = row.Line
+		outcome.EndToEndID = row.EndToEndID
+		outcome.Status, outcome.LoanID, outcome.Error = s.applyResult(ctx, row)
+
+		switch outcome.Status {
+		case ResultStatusCollected:
+			report.Collected++
+		case ResultStatusFailed:
+			report.Failed++
+		case ResultStatusUnapplied:
+			report.Unapplied++
+		default:
+			report.Skipped++
+		}
+	}
+	s.logger.InfoContext(ctx, "Direct-debit results processed",
+		slog.Int("total", report.Total), slog.Int("collected", report.Collected),
+		slog.Int("failed", report.Failed), slog.Int("unapplied", report.Unapplied), slog.Int("skipped", report.Skipped))
+	return report
+}
+
+func (s *service) applyResult(ctx context.Context, row ResultRow) (status string, loanID int64, message string) {
+	if row.EndToEndID == "" {
+		return ResultStatusSkipped, 0, "end_to_end_id is empty"
+	}
+	instruction, err := s.repo.GetInstructionByEndToEndID(ctx, row.EndToEndID)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return ResultStatusSkipped, 0, "unknown end_to_end_id"
+	}
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed to look up instruction", slog.String("endToEndID", row.EndToEndID), slog.Any("error", err))
+		return ResultStatusSkipped, 0, "instruction lookup failed, retry the file"
+	}
+	if instruction.Status != InstructionExported {
+		return ResultStatusSkipped, instruction.LoanID, fmt.Sprintf("instruction is already %s", strings.ToLower(string(instruction.Status)))
+	}
+
+	if !row.Collected {
+		reason := row.Reason
+		if reason == "" {
+			reason = "rejected by the bank"
+		}
+		if err := s.repo.TransitionInstruction(ctx, instruction.ID, InstructionExported, InstructionFailed, reason); err != nil {
+			return ResultStatusSkipped, instruction.LoanID, transitionMessage(err)
+		}
+		return ResultStatusFailed, instruction.LoanID, ""
+	}
+
+	// Claim the instruction before posting, so a concurrent upload of the
+	// same file cannot post the payment again.
+	if err := s.repo.TransitionInstruction(ctx, instruction.ID, InstructionExported, InstructionCollected, ""); err != nil {
+		return ResultStatusSkipped, instruction.LoanID, transitionMessage(err)
+	}
+	details := loan.PaymentDetails{Channel: loan.ChannelDirectDebit, Reference: instruction.EndToEndID}
+	if err := s.payments.MakePayment(ctx, instruction.LoanID, instruction.Amount, details); err != nil {
+		s.logger.ErrorContext(ctx, "Collected direct debit could not be posted",
+			slog.String("endToEndID", row.EndToEndID), slog.Int64("loanID", instruction.LoanID), slog.Any("error", err))
+		reason := fmt.Sprintf("collected but not posted: %v", err)
+		if err := s.repo.TransitionInstruction(ctx, instruction.ID, InstructionCollected, InstructionUnapplied, reason); err != nil {
+			s.logger.ErrorContext(ctx, "Failed to mark instruction unapplied", slog.String("endToEndID", row.EndToEndID), slog.Any("error", err))
+		}
+		return ResultStatusUnapplied, instruction.LoanID, reason
+	}
+	return ResultStatusCollected, instruction.LoanID, ""
+}
+
+func transitionMessage(err error) string {
+	if errors.Is(err, apperrors.ErrConflict) {
+		return "instruction was processed concurrently"
+	}
+	return "status update failed, retry the file"
+}
+
+func truncateDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
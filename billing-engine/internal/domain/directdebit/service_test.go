@@ -0,0 +1,282 @@
+package directdebit
+
+import (
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+var testClock = clock.NewFake(time.Date(2025, 1, 6, 6, 0, 0, 0, time.UTC))
+
+func day(s string) time.Time {
+	d, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func testConfig(format string) Config {
+	cfg := Config{
+		Format:          format,
+		LeadDays:        2,
+		Creditor:        Creditor{Name: "Billing Engine Finance", AccountNumber: "1234567890", BankCode: "BANKIDJA", SchemeID: "ID00ZZZ123"},
+		Currency:        "IDR",
+		MinorUnitDigits: 2,
+	}
+	if err := cfg.Validate(); err != nil {
+		panic(err)
+	}
+	return cfg
+}
+
+func newTestService(repo *MockRepository, poster *MockPaymentPoster, format string) Service {
+	return NewService(repo, poster, testConfig(format), testClock, testLogger)
+}
+
+var testMandate = Mandate{
+	ID:            3,
+	CustomerID:    7,
+	Reference:     "MNDT-7",
+	AccountHolder: "Jane Doe",
+	AccountNumber: "9876543210",
+	BankCode:      "BANKIDJB",
+	Status:        MandateActive,
+	SignedAt:      day("2024-12-20"),
+}
+
+func TestConfigValidate(t *testing.T) {
+	cfg := Config{}
+	require.NoError(t, cfg.Validate())
+	assert.Equal(t, FormatCSV, cfg.Format)
+	assert.Equal(t, DefaultHorizonDays, cfg.HorizonDays)
+
+	assert.ErrorIs(t, (&Config{Format: "mt940"}).Validate(), apperrors.ErrInvalidArgument)
+	assert.ErrorIs(t, (&Config{LeadDays: -1}).Validate(), apperrors.ErrInvalidArgument)
+}
+
+func TestServiceCreateMandate(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("normalizes the account and generates a reference", func(t *testing.T) {
+		repo := new(MockRepository)
+		repo.On("CreateMandate", ctx, mock.MatchedBy(func(m *Mandate) bool {
+			m.ID = 11
+			return m.AccountNumber == "ID12BANK0001" && m.BankCode == "BANKIDJA" &&
+				strings.HasPrefix(m.Reference, "MNDT-") && m.Status == MandateActive
+		})).Return(nil).Once()
+
+		created, err := newTestService(repo, new(MockPaymentPoster), FormatCSV).CreateMandate(ctx, &Mandate{
+			CustomerID: 7, AccountHolder: " Jane Doe ", AccountNumber: "id12 bank 0001", BankCode: "bankidja", SignedAt: day("2025-01-02"),
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(11), created.ID)
+		assert.Equal(t, "Jane Doe", created.AccountHolder)
+		assert.LessOrEqual(t, len(created.Reference), MaxReferenceLength)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("rejects an invalid account number", func(t *testing.T) {
+		repo := new(MockRepository)
+
+		_, err := newTestService(repo, new(MockPaymentPoster), FormatCSV).CreateMandate(ctx, &Mandate{
+			CustomerID: 7, AccountHolder: "Jane Doe", AccountNumber: "12-34", BankCode: "BANKIDJA", SignedAt: day("2025-01-02"),
+		})
+
+		assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+		repo.AssertNotCalled(t, "CreateMandate", mock.Anything, mock.Anything)
+	})
+
+	t.Run("passes on a second active mandate", func(t *testing.T) {
+		repo := new(MockRepository)
+		repo.On("CreateMandate", ctx, mock.Anything).Return(apperrors.ErrConflict).Once()
+
+		_, err := newTestService(repo, new(MockPaymentPoster), FormatCSV).CreateMandate(ctx, &testMandate)
+
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+	})
+}
+
+func TestServiceGenerateInstructions(t *testing.T) {
+	ctx := context.Background()
+	repo := new(MockRepository)
+	svc := newTestService(repo, new(MockPaymentPoster), FormatCSV)
+
+	repo.On("ListUncoveredInstallments", ctx, day("2025-01-13")).Return([]Installment{
+		{Mandate: testMandate, LoanID: 5, ScheduleID: 50, WeekNumber: 1, DueDate: day("2025-01-06"), Amount: 110},
+		{Mandate: testMandate, LoanID: 5, ScheduleID: 51, WeekNumber: 2, DueDate: day("2025-01-13"), Amount: 110},
+		{Mandate: testMandate, LoanID: 5, ScheduleID: 52, WeekNumber: 3, DueDate: day("2025-01-13"), Amount: 0},
+		{Mandate: testMandate, LoanID: 6, ScheduleID: 60, WeekNumber: 1, DueDate: day("2025-01-10"), Amount: 90},
+	}, nil).Once()
+
+	var created []*Instruction
+	repo.On("CreateInstruction", ctx, mock.MatchedBy(func(in *Instruction) bool { return in.ScheduleID != 60 })).
+		Run(func(args mock.Arguments) { created = append(created, args.Get(1).(*Instruction)) }).
+		Return(nil).Twice()
+	repo.On("CreateInstruction", ctx, mock.MatchedBy(func(in *Instruction) bool { return in.ScheduleID == 60 })).
+		Return(apperrors.ErrAlreadyExists).Once()
+
+	n, err := svc.GenerateInstructions(ctx, testClock.Now())
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, n, "zero amounts and installments covered concurrently are skipped")
+	require.Len(t, created, 2)
+	assert.Equal(t, day("2025-01-08"), created[0].CollectionDate, "overdue installments are collected after the lead time")
+	assert.Equal(t, day("2025-01-13"), created[1].CollectionDate)
+	assert.Equal(t, InstructionPending, created[0].Status)
+	assert.Len(t, created[0].EndToEndID, 32)
+	assert.NotEqual(t, created[0].EndToEndID, created[1].EndToEndID)
+	repo.AssertExpectations(t)
+}
+
+func pendingInstructions() []Instruction {
+	return []Instruction{
+		{ID: 1, MandateID: 3, LoanID: 5, WeekNumber: 1, EndToEndID: "E2E1", Amount: 110, CollectionDate: day("2025-01-08"), Status: InstructionPending, Mandate: testMandate},
+		{ID: 2, MandateID: 3, LoanID: 5, WeekNumber: 2, EndToEndID: "E2E2", Amount: 110.5, CollectionDate: day("2025-01-13"), Status: InstructionPending, Mandate: testMandate},
+	}
+}
+
+func TestServiceExportBatch(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("writes nothing without pending instructions", func(t *testing.T) {
+		repo := new(MockRepository)
+		repo.On("ListInstructions", ctx, InstructionPending).Return([]Instruction{}, nil).Once()
+		var buf bytes.Buffer
+
+		batch, err := newTestService(repo, new(MockPaymentPoster), FormatCSV).ExportBatch(ctx, &buf)
+
+		require.NoError(t, err)
+		assert.Zero(t, batch.Instructions)
+		assert.Zero(t, buf.Len())
+		repo.AssertNotCalled(t, "MarkExported", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("writes a CSV file and marks the instructions exported", func(t *testing.T) {
+		repo := new(MockRepository)
+		repo.On("ListInstructions", ctx, InstructionPending).Return(pendingInstructions(), nil).Once()
+		repo.On("MarkExported", ctx, []int64{1, 2}, mock.MatchedBy(func(ref string) bool {
+			return strings.HasPrefix(ref, "DD-20250106-")
+		})).Return(int64(2), nil).Once()
+		var buf bytes.Buffer
+
+		batch, err := newTestService(repo, new(MockPaymentPoster), FormatCSV).ExportBatch(ctx, &buf)
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, batch.Instructions)
+		assert.InDelta(t, 220.5, batch.Total, 0.001)
+		assert.Equal(t, FormatCSV, batch.Format)
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 3)
+		assert.Equal(t, strings.Join(csvExportHeader, ","), lines[0])
+		assert.Equal(t, "E2E2,MNDT-7,2024-12-20,Jane Doe,9876543210,BANKIDJB,110.50,IDR,2025-01-13,Loan 5 installment 2", lines[2])
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("fails when another export took some of the instructions", func(t *testing.T) {
+		repo := new(MockRepository)
+		repo.On("ListInstructions", ctx, InstructionPending).Return(pendingInstructions(), nil).Once()
+		repo.On("MarkExported", ctx, []int64{1, 2}, mock.Anything).Return(int64(1), nil).Once()
+
+		_, err := newTestService(repo, new(MockPaymentPoster), FormatCSV).ExportBatch(ctx, io.Discard)
+
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+	})
+}
+
+func TestServiceProcessResults(t *testing.T) {
+	ctx := context.Background()
+	exported := func(id int64, e2e string) *Instruction {
+		return &Instruction{ID: id, LoanID: 5, EndToEndID: e2e, Amount: 110, Status: InstructionExported}
+	}
+
+	repo := new(MockRepository)
+	poster := new(MockPaymentPoster)
+	svc := newTestService(repo, poster, FormatCSV)
+
+	repo.On("GetInstructionByEndToEndID", ctx, "OK").Return(exported(1, "OK"), nil).Once()
+	repo.On("TransitionInstruction", ctx, int64(1), InstructionExported, InstructionCollected, "").Return(nil).Once()
+	poster.On("MakePayment", ctx, int64(5), 110.0,
+		loan.PaymentDetails{Channel: loan.ChannelDirectDebit, Reference: "OK"}).Return(nil).Once()
+
+	repo.On("GetInstructionByEndToEndID", ctx, "NSF").Return(exported(2, "NSF"), nil).Once()
+	repo.On("TransitionInstruction", ctx, int64(2), InstructionExported, InstructionFailed, "AM04 insufficient funds").Return(nil).Once()
+
+	repo.On("GetInstructionByEndToEndID", ctx, "POSTFAIL").Return(exported(3, "POSTFAIL"), nil).Once()
+	repo.On("TransitionInstruction", ctx, int64(3), InstructionExported, InstructionCollected, "").Return(nil).Once()
+	poster.On("MakePayment", ctx, int64(5), 110.0,
+		loan.PaymentDetails{Channel: loan.ChannelDirectDebit, Reference: "POSTFAIL"}).Return(apperrors.ErrLoanFullyPaid).Once()
+	repo.On("TransitionInstruction", ctx, int64(3), InstructionCollected, InstructionUnapplied,
+		"collected but not posted: loan is already fully paid").Return(nil).Once()
+
+	done := exported(4, "DONE")
+	done.Status = InstructionCollected
+	repo.On("GetInstructionByEndToEndID", ctx, "DONE").Return(done, nil).Once()
+	repo.On("GetInstructionByEndToEndID", ctx, "NOPE").Return(nil, apperrors.ErrNotFound).Once()
+
+	report := svc.ProcessResults(ctx, []ResultRow{
+		{Line: 2, EndToEndID: "OK", Collected: true},
+		{Line: 3, EndToEndID: "NSF", Reason: "AM04 insufficient funds"},
+		{Line: 4, EndToEndID: "POSTFAIL", Collected: true},
+		{Line: 5, EndToEndID: "DONE", Collected: true},
+		{Line: 6, EndToEndID: "NOPE", Collected: true},
+		{Line: 7},
+	}, nil)
+
+	assert.Equal(t, 6, report.Total)
+	assert.Equal(t, 1, report.Collected)
+	assert.Equal(t, 1, report.Failed)
+	assert.Equal(t, 1, report.Unapplied)
+	assert.Equal(t, 3, report.Skipped)
+	assert.Equal(t, ResultOutcome{Line: 4, EndToEndID: "POSTFAIL", Status: ResultStatusUnapplied, LoanID: 5,
+		Error: "collected but not posted: loan is already fully paid"}, report.Results[2])
+	assert.Equal(t, "instruction is already collected", report.Results[3].Error, "uploading a file twice posts nothing")
+	assert.Equal(t, "unknown end_to_end_id", report.Results[4].Error)
+	repo.AssertExpectations(t)
+	poster.AssertExpectations(t)
+}
+
+func TestServiceProcessResultsConcurrentUpload(t *testing.T) {
+	ctx := context.Background()
+	repo := new(MockRepository)
+	poster := new(MockPaymentPoster)
+	repo.On("GetInstructionByEndToEndID", ctx, "OK").
+		Return(&Instruction{ID: 1, LoanID: 5, Amount: 110, Status: InstructionExported}, nil).Once()
+	repo.On("TransitionInstruction", ctx, int64(1), InstructionExported, InstructionCollected, "").
+		Return(fmt.Errorf("%w: instruction 1 is no longer EXPORTED", apperrors.ErrConflict)).Once()
+
+	report := newTestService(repo, poster, FormatCSV).ProcessResults(ctx, []ResultRow{{Line: 2, EndToEndID: "OK", Collected: true}}, nil)
+
+	assert.Equal(t, 1, report.Skipped)
+	assert.Equal(t, "instruction was processed concurrently", report.Results[0].Error)
+	poster.AssertNotCalled(t, "MakePayment", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestServiceProcessResultsReportsProgress(t *testing.T) {
+	rows := make([]ResultRow, 120)
+	for i := range rows {
+		rows[i].Line = i + 2
+	}
+	var progress []int
+
+	report := newTestService(new(MockRepository), new(MockPaymentPoster), FormatCSV).
+		ProcessResults(context.Background(), rows, func(processed int) { progress = append(progress, processed) })
+
+	assert.Equal(t, 120, report.Skipped)
+	assert.Equal(t, []int{50, 100}, progress)
+}
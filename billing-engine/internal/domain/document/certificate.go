@@ -0,0 +1,94 @@
+package document
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+//go:embed payoff_certificate.tmpl
+var payoffCertificateText string
+
+//go:embed loan_agreement.tmpl
+var loanAgreementText string
+
+// certificateData is what the payoff certificate template is executed with.
+type certificateData struct {
+	Loan     PaidOffLoan
+	IssuedAt time.Time
+}
+
+// agreementData is what the loan agreement template is executed with.
+type agreementData struct {
+	Loan     LoanAgreement
+	IssuedAt time.Time
+}
+
+// renderer fills in the documents the engine generates and lays them out as
+// PDFs. Dates are printed in location and amounts in currency, rounded to
+// digits decimals.
+type renderer struct {
+	template *template.Template
+}
+
+func newRenderer(location *time.Location, currency string, digits int) *renderer {
+	if location == nil {
+		location = time.UTC
+	}
+	funcs := template.FuncMap{
+		"date": func(t time.Time) string { return t.In(location).Format("2 January 2006") },
+		"money": func(amount float64) string {
+			if currency == "" {
+				return formatAmount(amount, digits)
+			}
+			return currency + " " + formatAmount(amount, digits)
+		},
+		"percent": func(rate float64) string { return strconv.FormatFloat(rate*100, 'f', -1, 64) + "%" },
+	}
+	t := template.Must(template.New("payoff_certificate").Funcs(funcs).Parse(payoffCertificateText))
+	template.Must(t.New("loan_agreement").Parse(loanAgreementText))
+	return &renderer{template: t}
+}
+
+func (r *renderer) payoffCertificate(loan PaidOffLoan, issuedAt time.Time) ([]byte, error) {
+	var text bytes.Buffer
+	if err := r.template.ExecuteTemplate(&text, "payoff_certificate", certificateData{Loan: loan, IssuedAt: issuedAt}); err != nil {
+		return nil, fmt.Errorf("failed to fill in the payoff certificate of loan %d: %w", loan.LoanID, err)
+	}
+	return renderPDF(textLines(text.String())), nil
+}
+
+func (r *renderer) loanAgreement(loan LoanAgreement, issuedAt time.Time) ([]byte, error) {
+	var text bytes.Buffer
+	if err := r.template.ExecuteTemplate(&text, "loan_agreement", agreementData{Loan: loan, IssuedAt: issuedAt}); err != nil {
+		return nil, fmt.Errorf("failed to fill in the agreement of loan %d: %w", loan.LoanID, err)
+	}
+	return renderPDF(textLines(text.String())), nil
+}
+
+// formatAmount groups the whole part of amount in thousands, as in
+// 1,250,000.00.
+func formatAmount(amount float64, digits int) string {
+	formatted := strconv.FormatFloat(amount, 'f', max(digits, 0), 64)
+	sign := ""
+	if strings.HasPrefix(formatted, "-") {
+		sign, formatted = "-", formatted[1:]
+	}
+	whole, fraction, hasFraction := strings.Cut(formatted, ".")
+	var b strings.Builder
+	b.WriteString(sign)
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(digit)
+	}
+	if hasFraction {
+		b.WriteString("." + fraction)
+	}
+	return b.String()
+}
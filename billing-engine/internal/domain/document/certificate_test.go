@@ -0,0 +1,98 @@
+package document
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatAmount(t *testing.T) {
+	assert.Equal(t, "1,250,000.00", formatAmount(1_250_000, 2))
+	assert.Equal(t, "-999.50", formatAmount(-999.5, 2))
+	assert.Equal(t, "12,345", formatAmount(12_345.4, 0))
+	assert.Equal(t, "0.00", formatAmount(0, 2))
+}
+
+func TestCertificateRender(t *testing.T) {
+	jakarta := time.FixedZone("WIB", 7*3600)
+	loan := PaidOffLoan{
+		LoanID: 42, PublicID: uuid.MustParse("0b9d5f3e-1c2a-4e4b-9f57-1d2e3f4a5b6c"), ExternalRef: "EXT-42", CustomerName: "Siti (Ani)",
+		Principal: 5_000_000, TotalRepaid: 5_500_000,
+		StartDate: time.Date(2024, 9, 2, 0, 0, 0, 0, time.UTC),
+		PaidOffAt: time.Date(2025, 3, 2, 20, 0, 0, 0, time.UTC),
+	}
+
+	content, err := newRenderer(jakarta, "IDR", 2).payoffCertificate(loan, time.Date(2025, 3, 2, 18, 0, 0, 0, time.UTC))
+
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(content, []byte("%PDF-1.4\n")))
+	assert.True(t, bytes.HasSuffix(content, []byte("%%EOF\n")))
+	text := string(content)
+	assert.Contains(t, text, "(Payoff Certificate) Tj")
+	assert.Contains(t, text, "loan 42 \\(EXT-42\\), held by Siti \\(Ani\\),", "parentheses are escaped")
+	assert.Contains(t, text, "(Issued 3 March 2025) Tj", "dates are printed in the configured zone")
+	assert.Contains(t, text, "(Total repaid: IDR 5,500,000.00) Tj")
+	assert.Contains(t, text, "/Count 1")
+}
+
+func TestLoanAgreementRender(t *testing.T) {
+	firstDue := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+	loan := LoanAgreement{
+		LoanID: 42, PublicID: uuid.MustParse("0b9d5f3e-1c2a-4e4b-9f57-1d2e3f4a5b6c"), CustomerName: "Siti",
+		Principal: 5_000_000, AnnualInterestRate: 0.125, TermWeeks: 50, WeeklyInstallment: 112_500, TotalAmount: 5_625_000,
+		FirstDueDate: &firstDue,
+	}
+
+	content, err := newRenderer(time.UTC, "IDR", 2).loanAgreement(loan, time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC))
+
+	require.NoError(t, err)
+	text := string(content)
+	assert.Contains(t, text, "(Loan Agreement) Tj")
+	assert.Contains(t, text, "(Annual interest rate: 12.5%) Tj")
+	assert.Contains(t, text, "(Term: 50 weekly installments of IDR 112,500.00) Tj")
+	assert.Contains(t, text, "(First installment due: 10 March 2025) Tj")
+
+	loan.FirstDueDate = nil
+	content, err = newRenderer(time.UTC, "IDR", 2).loanAgreement(loan, time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC))
+
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "First installment due")
+}
+
+func TestRenderPDFPagesAndOffsets(t *testing.T) {
+	lines := make([]pdfLine, 80)
+	for i := range lines {
+		lines[i] = pdfLine{text: "line"}
+	}
+
+	content := string(renderPDF(lines))
+
+	assert.Contains(t, content, "/Count 2", "lines past the bottom margin start a new page")
+	trailer := content[strings.LastIndex(content, "startxref\n")+len("startxref\n"):]
+	offset, err := strconv.Atoi(strings.TrimSuffix(trailer, "\n%%EOF\n"))
+	require.NoError(t, err)
+	assert.Equal(t, strings.Index(content, "xref\n"), offset, "startxref points at the xref table")
+}
+
+func TestPDFString(t *testing.T) {
+	assert.Equal(t, `a\(b\)\\c`, pdfString(`a(b)\c`))
+	assert.Equal(t, `Jos\351`, pdfString("José"))
+	assert.Equal(t, "?", pdfString("€"))
+}
+
+func TestTextLinesWrapsLongLines(t *testing.T) {
+	lines := textLines("# Title\n\n" + strings.Repeat("word ", 40))
+
+	require.Len(t, lines, 5)
+	assert.Equal(t, pdfLine{text: "Title", heading: true}, lines[0])
+	assert.Equal(t, pdfLine{}, lines[1])
+	for _, l := range lines[2:] {
+		assert.LessOrEqual(t, len(l.text), maxLineRunes)
+	}
+}
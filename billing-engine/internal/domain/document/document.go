@@ -0,0 +1,176 @@
+package document
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+type Type string
+
+const (
+	// TypeContract is a loan agreement: generated for a loan created in
+	// workflow mode, before it is signed, or uploaded against the loan once
+	// signed.
+	TypeContract Type = "CONTRACT"
+	// TypeIDCopy is a copy of the customer's identity document, uploaded
+	// against the customer.
+	TypeIDCopy Type = "ID_COPY"
+	// TypePayoffCertificate, the payoff letter, states that a loan was
+	// repaid in full. The engine issues it once per loan, after the loan is
+	// paid off; it cannot be uploaded.
+	TypePayoffCertificate Type = "PAYOFF_CERTIFICATE"
+
+	ContentTypePDF = "application/pdf"
+
+	MaxFileNameLength = 255
+)
+
+// Types lists every document type, in the order they are reported.
+var Types = []Type{TypeContract, TypeIDCopy, TypePayoffCertificate}
+
+// uploadSubjects says which subject each uploadable type is filed under.
+var uploadSubjects = map[Type]SubjectType{
+	TypeContract: SubjectLoan,
+	TypeIDCopy:   SubjectCustomer,
+}
+
+// uploadContentTypes are the file types staff may upload, with the
+// extension their storage key gets.
+var uploadContentTypes = map[string]string{
+	ContentTypePDF: ".pdf",
+	"image/jpeg":   ".jpg",
+	"image/png":    ".png",
+}
+
+func ParseType(s string) (Type, error) {
+	t := Type(strings.ToUpper(strings.TrimSpace(s)))
+	for _, known := range Types {
+		if t == known {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("unknown document type %q", s)
+}
+
+// Status says whether a document's content is in object storage. An upload
+// is PENDING from the moment its upload link is handed out until the client
+// confirms it; generated documents are AVAILABLE at once.
+type Status string
+
+const (
+	StatusPending   Status = "PENDING"
+	StatusAvailable Status = "AVAILABLE"
+)
+
+type SubjectType string
+
+const (
+	SubjectLoan     SubjectType = "loan"
+	SubjectCustomer SubjectType = "customer"
+)
+
+// Subject is the loan or customer a document belongs to.
+type Subject struct {
+	Type SubjectType
+	ID   int64
+}
+
+func LoanSubject(loanID int64) Subject {
+	return Subject{Type: SubjectLoan, ID: loanID}
+}
+
+func (s Subject) Validate() error {
+	if s.Type != SubjectLoan && s.Type != SubjectCustomer {
+		return fmt.Errorf("unknown subject type %q", s.Type)
+	}
+	if s.ID <= 0 {
+		return fmt.Errorf("%s ID must be a positive number", s.Type)
+	}
+	return nil
+}
+
+func (s Subject) String() string {
+	return fmt.Sprintf("%s %d", s.Type, s.ID)
+}
+
+// Document holds the metadata of a file kept for a loan or customer, either
+// generated by the engine or uploaded by staff. Its content lives in object
+// storage under StorageKey. UploadedBy is empty for generated documents.
+type Document struct {
+	ID          int64
+	Subject     Subject
+	Type        Type
+	Status      Status
+	FileName    string
+	ContentType string
+	SizeBytes   int64
+	StorageKey  string
+	UploadedBy  string
+	CreatedAt   time.Time
+}
+
+// PaidOffLoan is what a payoff certificate states about a loan. PaidOffAt is
+// the loan's last payment, or its last update when it was paid off without
+// one. CustomerID is nil, and CustomerName empty, for a loan no customer
+// holds.
+type PaidOffLoan struct {
+	LoanID       int64
+	PublicID     uuid.UUID
+	ExternalRef  string
+	CustomerID   *int64
+	CustomerName string
+	Principal    float64
+	TotalRepaid  float64
+	StartDate    time.Time
+	PaidOffAt    time.Time
+}
+
+// LoanAgreement is what the agreement of a loan created in workflow mode
+// states, for the customer to sign before the loan is disbursed.
+// FirstDueDate is nil when the schedule is not known.
+type LoanAgreement struct {
+	LoanID             int64
+	PublicID           uuid.UUID
+	ExternalRef        string
+	CustomerName       string
+	Principal          float64
+	AnnualInterestRate float64
+	TermWeeks          int
+	WeeklyInstallment  float64
+	TotalAmount        float64
+	FirstDueDate       *time.Time
+}
+
+// payoffCertificateFileName is what the certificate of loanID is downloaded
+// as.
+func payoffCertificateFileName(loanID int64) string {
+	return fmt.Sprintf("payoff-certificate-%d.pdf", loanID)
+}
+
+func loanAgreementFileName(loanID int64) string {
+	return fmt.Sprintf("loan-agreement-%d.pdf", loanID)
+}
+
+// storageKey is unique per document, so content stored by an attempt that
+// then failed to record it never clashes with the next attempt.
+func storageKey(subject Subject, extension string) string {
+	return fmt.Sprintf("%ss/%d/documents/%s%s", subject.Type, subject.ID, uuid.New(), extension)
+}
+
+// cleanFileName drops any directory part a client may send along with the
+// file name.
+func cleanFileName(name string) (string, error) {
+	name = strings.TrimSpace(path.Base(strings.ReplaceAll(name, "\\", "/")))
+	switch {
+	case name == "" || name == "." || name == "/":
+		return "", fmt.Errorf("file name cannot be empty")
+	case utf8.RuneCountInString(name) > MaxFileNameLength:
+		return "", fmt.Errorf("file name cannot exceed %d characters", MaxFileNameLength)
+	}
+	return name, nil
+}
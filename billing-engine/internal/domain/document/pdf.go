@@ -0,0 +1,142 @@
+package document
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// A4 page in points, with the margins the text keeps to.
+const (
+	pageWidth    = 595
+	pageHeight   = 842
+	pageMargin   = 72
+	bodySize     = 11
+	headingSize  = 20
+	lineSpacing  = 1.5
+	maxLineRunes = 85
+)
+
+// pdfLine is one line of text. A heading is set larger, in bold.
+type pdfLine struct {
+	text    string
+	heading bool
+}
+
+// textLines turns plain text into lines: a line starting with "# " is a
+// heading, and longer lines wrap between words.
+func textLines(text string) []pdfLine {
+	var lines []pdfLine
+	for _, raw := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		raw = strings.TrimRight(raw, " \t\r")
+		if heading, ok := strings.CutPrefix(raw, "# "); ok {
+			lines = append(lines, pdfLine{text: heading, heading: true})
+			continue
+		}
+		for _, wrapped := range wrap(raw, maxLineRunes) {
+			lines = append(lines, pdfLine{text: wrapped})
+		}
+	}
+	return lines
+}
+
+func wrap(line string, width int) []string {
+	words := strings.Fields(line)
+	if len(words) == 0 {
+		return []string{""}
+	}
+	var out []string
+	current := words[0]
+	for _, word := range words[1:] {
+		if utf8.RuneCountInString(current)+1+utf8.RuneCountInString(word) > width {
+			out = append(out, current)
+			current = word
+			continue
+		}
+		current += " " + word
+	}
+	return append(out, current)
+}
+
+// renderPDF lays lines out top to bottom on as many A4 pages as they need,
+// in the Helvetica fonts every PDF reader has, so the file embeds no font.
+// Those fonts cover Latin-1; other characters print as a question mark.
+func renderPDF(lines []pdfLine) []byte {
+	var pages []string
+	var content strings.Builder
+	y := float64(pageHeight - pageMargin)
+	for _, line := range lines {
+		size, font := float64(bodySize), "F1"
+		if line.heading {
+			size, font = headingSize, "F2"
+		}
+		if y-size < pageMargin && content.Len() > 0 {
+			pages = append(pages, content.String())
+			content.Reset()
+			y = pageHeight - pageMargin
+		}
+		y -= size
+		if line.text != "" {
+			fmt.Fprintf(&content, "BT /%s %g Tf %d %g Td (%s) Tj ET\n", font, size, pageMargin, y, pdfString(line.text))
+		}
+		y -= size * (lineSpacing - 1)
+	}
+	pages = append(pages, content.String())
+
+	// Objects 1 and 2 are the catalog and the page tree, 3 and 4 the fonts,
+	// and every page takes two more: the page and its content stream.
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>",
+	}
+	kids := make([]string, len(pages))
+	for i, stream := range pages {
+		pageObject := len(objects) + 1
+		kids[i] = fmt.Sprintf("%d 0 R", pageObject)
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+				pageWidth, pageHeight, pageObject+1),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(stream), stream))
+	}
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages))
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return out.Bytes()
+}
+
+// pdfString encodes s for a literal string in WinAnsiEncoding, which
+// matches Latin-1 from U+00A0 up.
+func pdfString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		case r == '\t':
+			b.WriteByte(' ')
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
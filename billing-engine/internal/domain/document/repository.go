@@ -0,0 +1,61 @@
+package document
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+type Repository interface {
+	// ListUncertified returns, oldest first, at most limit paid-off loans
+	// with no payoff certificate that were paid off at or after since.
+	ListUncertified(ctx context.Context, since time.Time, limit int) ([]PaidOffLoan, error)
+
+	// Create stores d. It returns ErrNotFound for an unknown subject and
+	// ErrConflict when d is a payoff certificate and the loan already has
+	// one.
+	Create(ctx context.Context, d *Document) error
+
+	// List returns the subject's documents, newest first.
+	List(ctx context.Context, subject Subject) ([]Document, error)
+
+	Get(ctx context.Context, subject Subject, documentID int64) (*Document, error)
+
+	// MarkAvailable moves a pending document to AVAILABLE. It returns
+	// ErrNotFound when the subject has no such pending document.
+	MarkAvailable(ctx context.Context, subject Subject, documentID int64) error
+
+	Delete(ctx context.Context, subject Subject, documentID int64) error
+
+	// ListExpired returns, oldest first, at most limit available documents
+	// of type t created before before. Documents of loans under an active
+	// hold are left out.
+	ListExpired(ctx context.Context, t Type, before time.Time, limit int) ([]Document, error)
+
+	// ListStalePending returns, oldest first, at most limit pending
+	// documents created before before.
+	ListStalePending(ctx context.Context, before time.Time, limit int) ([]Document, error)
+
+	SubjectExists(ctx context.Context, subject Subject) (bool, error)
+}
+
+// ObjectStore keeps document content. PresignGet and PresignPut return a
+// URL anyone can download the object from, or upload it to, until expires
+// has passed.
+type ObjectStore interface {
+	Put(ctx context.Context, key, contentType string, body io.Reader, size int64) error
+
+	// Get returns ErrNotFound when no object is stored under key.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// Size returns the length of the object, or ErrNotFound when no object
+	// is stored under key.
+	Size(ctx context.Context, key string) (int64, error)
+
+	Delete(ctx context.Context, key string) error
+
+	PresignGet(key string, expires time.Duration) (string, error)
+
+	// PresignPut signs an upload that must be sent with contentType.
+	PresignPut(key, contentType string, expires time.Duration) (string, error)
+}
@@ -0,0 +1,93 @@
+package document
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) ListUncertified(ctx context.Context, since time.Time, limit int) ([]PaidOffLoan, error) {
+	args := m.Called(ctx, since, limit)
+	loans, _ := args.Get(0).([]PaidOffLoan)
+	return loans, args.Error(1)
+}
+
+func (m *MockRepository) Create(ctx context.Context, d *Document) error {
+	return m.Called(ctx, d).Error(0)
+}
+
+func (m *MockRepository) List(ctx context.Context, subject Subject) ([]Document, error) {
+	args := m.Called(ctx, subject)
+	documents, _ := args.Get(0).([]Document)
+	return documents, args.Error(1)
+}
+
+func (m *MockRepository) Get(ctx context.Context, subject Subject, documentID int64) (*Document, error) {
+	args := m.Called(ctx, subject, documentID)
+	d, _ := args.Get(0).(*Document)
+	return d, args.Error(1)
+}
+
+func (m *MockRepository) MarkAvailable(ctx context.Context, subject Subject, documentID int64) error {
+	return m.Called(ctx, subject, documentID).Error(0)
+}
+
+func (m *MockRepository) Delete(ctx context.Context, subject Subject, documentID int64) error {
+	return m.Called(ctx, subject, documentID).Error(0)
+}
+
+func (m *MockRepository) ListExpired(ctx context.Context, t Type, before time.Time, limit int) ([]Document, error) {
+	args := m.Called(ctx, t, before, limit)
+	documents, _ := args.Get(0).([]Document)
+	return documents, args.Error(1)
+}
+
+func (m *MockRepository) ListStalePending(ctx context.Context, before time.Time, limit int) ([]Document, error) {
+	args := m.Called(ctx, before, limit)
+	documents, _ := args.Get(0).([]Document)
+	return documents, args.Error(1)
+}
+
+func (m *MockRepository) SubjectExists(ctx context.Context, subject Subject) (bool, error) {
+	args := m.Called(ctx, subject)
+	return args.Bool(0), args.Error(1)
+}
+
+type MockObjectStore struct {
+	mock.Mock
+}
+
+func (m *MockObjectStore) Put(ctx context.Context, key, contentType string, body io.Reader, size int64) error {
+	return m.Called(ctx, key, contentType, body, size).Error(0)
+}
+
+func (m *MockObjectStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	args := m.Called(ctx, key)
+	body, _ := args.Get(0).(io.ReadCloser)
+	return body, args.Error(1)
+}
+
+func (m *MockObjectStore) Size(ctx context.Context, key string) (int64, error) {
+	args := m.Called(ctx, key)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockObjectStore) Delete(ctx context.Context, key string) error {
+	return m.Called(ctx, key).Error(0)
+}
+
+func (m *MockObjectStore) PresignGet(key string, expires time.Duration) (string, error) {
+	args := m.Called(key, expires)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockObjectStore) PresignPut(key, contentType string, expires time.Duration) (string, error) {
+	args := m.Called(key, contentType, expires)
+	return args.String(0), args.Error(1)
+}
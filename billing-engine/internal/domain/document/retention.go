@@ -0,0 +1,43 @@
+package document
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"fmt"
+	"time"
+)
+
+// RetentionPolicy says how long documents of each type are kept after they
+// were created. Types without a period are kept for good.
+type RetentionPolicy struct {
+	Periods map[Type]time.Duration
+}
+
+// NewRetentionPolicy validates the configured periods. Types are matched
+// without regard to case, because configuration keys are lowercased when
+// they are read.
+func NewRetentionPolicy(periods map[string]time.Duration) (RetentionPolicy, error) {
+	p := RetentionPolicy{Periods: make(map[Type]time.Duration, len(periods))}
+	for name, period := range periods {
+		t, err := ParseType(name)
+		if err != nil {
+			return RetentionPolicy{}, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err)
+		}
+		if period <= 0 {
+			return RetentionPolicy{}, fmt.Errorf("%w: retention period of %s must be above 0, got %s", apperrors.ErrInvalidArgument, t, period)
+		}
+		p.Periods[t] = period
+	}
+	return p, nil
+}
+
+// types returns the types with a retention period in the order of Types, so
+// that runs go through them the same way every time.
+func (p RetentionPolicy) types() []Type {
+	var types []Type
+	for _, t := range Types {
+		if _, ok := p.Periods[t]; ok {
+			types = append(types, t)
+		}
+	}
+	return types
+}
@@ -0,0 +1,27 @@
+package document
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRetentionPolicy(t *testing.T) {
+	p, err := NewRetentionPolicy(map[string]time.Duration{"id_copy": time.Hour, "CONTRACT": 2 * time.Hour})
+	require.NoError(t, err)
+	assert.Equal(t, map[Type]time.Duration{TypeIDCopy: time.Hour, TypeContract: 2 * time.Hour}, p.Periods)
+	assert.Equal(t, []Type{TypeContract, TypeIDCopy}, p.types())
+
+	_, err = NewRetentionPolicy(map[string]time.Duration{"passport": time.Hour})
+	assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+
+	_, err = NewRetentionPolicy(map[string]time.Duration{"ID_COPY": 0})
+	assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+
+	p, err = NewRetentionPolicy(nil)
+	require.NoError(t, err)
+	assert.Empty(t, p.types())
+}
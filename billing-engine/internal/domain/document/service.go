@@ -0,0 +1,514 @@
+package document
+
+import (
+	"billing-engine/internal/event"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultLinkTTL       = 7 * 24 * time.Hour
+	DefaultUploadLinkTTL = 15 * time.Minute
+	DefaultLookback      = 30 * 24 * time.Hour
+	DefaultBatchSize     = 100
+
+	// pendingUploadGrace is how long after its upload link expired a
+	// pending upload can still be confirmed before the retention run
+	// removes it.
+	pendingUploadGrace = 24 * time.Hour
+)
+
+type Service interface {
+	// IssuePayoffCertificates issues the certificates of the loans paid off
+	// since the last run. Each is stored, recorded on the loan and announced
+	// in a loan.paid_off event with a link to download it.
+	IssuePayoffCertificates(ctx context.Context) (*IssueReport, error)
+
+	// IssueLoanAgreement generates the agreement of a loan created in
+	// workflow mode, stores it as a contract of the loan and returns it with
+	// its content, which is sent for signature.
+	IssueLoanAgreement(ctx context.Context, agreement LoanAgreement) (*Document, []byte, error)
+
+	// RequestUpload records a pending document and returns the link its
+	// file is uploaded to. The client confirms the upload with
+	// ConfirmUpload once the file is stored.
+	RequestUpload(ctx context.Context, subject Subject, upload Upload) (*PendingUpload, error)
+
+	// ConfirmUpload checks that the file of a pending document arrived in
+	// object storage with the announced size and makes it available.
+	ConfirmUpload(ctx context.Context, subject Subject, documentID int64) (*Document, error)
+
+	List(ctx context.Context, subject Subject) ([]Document, error)
+
+	// Open returns the document and its content, which the caller closes.
+	Open(ctx context.Context, subject Subject, documentID int64) (*Document, io.ReadCloser, error)
+
+	Delete(ctx context.Context, subject Subject, documentID int64) error
+
+	// ApplyRetention removes the documents older than the retention period
+	// of their type, and the pending uploads that were never confirmed.
+	ApplyRetention(ctx context.Context) (*RetentionReport, error)
+}
+
+// Config sets what IssuePayoffCertificates issues. Only loans paid off
+// within Lookback get a certificate, so that turning certificates on does
+// not issue one for every loan ever paid off. LinkTTL is how long the link
+// in the event can be downloaded from. Dates on the certificate are printed
+// in Location, and amounts in Currency with MinorUnitDigits decimals.
+//
+// Uploads are refused above MaxUploadBytes, where 0 means no limit, and
+// their upload links are valid for UploadLinkTTL. One run issues at most
+// BatchSize certificates, and removes at most BatchSize documents of each
+// type that Retention gives a period.
+type Config struct {
+	LinkTTL         time.Duration
+	UploadLinkTTL   time.Duration
+	Lookback        time.Duration
+	BatchSize       int
+	MaxUploadBytes  int64
+	Retention       RetentionPolicy
+	Location        *time.Location
+	Currency        string
+	MinorUnitDigits int
+}
+
+// Upload describes a file a client is about to upload. SizeBytes is the
+// exact length of the file.
+type Upload struct {
+	Type        Type
+	FileName    string
+	ContentType string
+	SizeBytes   int64
+	UploadedBy  string
+}
+
+// PendingUpload is a recorded upload and the link its file is sent to with
+// an HTTP PUT, carrying Document.ContentType as its Content-Type, before
+// ExpiresAt.
+type PendingUpload struct {
+	Document  Document
+	URL       string
+	ExpiresAt time.Time
+}
+
+// IssueReport counts the certificates one run issued and the loans it
+// could not issue one for, which the next run tries again.
+type IssueReport struct {
+	Issued []Document
+	Failed int
+}
+
+// RetentionReport counts the documents one retention run removed, the
+// pending uploads among them that were never confirmed, and the documents
+// it failed to remove, which the next run tries again.
+type RetentionReport struct {
+	Expired   int
+	Abandoned int
+	Failed    int
+}
+
+var _ Service = (*service)(nil)
+
+type service struct {
+	repo     Repository
+	store    ObjectStore
+	events   *event.Buffer
+	renderer *renderer
+	cfg      Config
+	clock    clock.Clock
+	logger   *slog.Logger
+}
+
+// NewService wires the document service. store may be nil when no object
+// storage is configured: documents are listed but none are issued,
+// uploaded, downloaded or deleted. Zero values in cfg take the defaults
+// above.
+func NewService(repo Repository, store ObjectStore, events *event.Buffer, cfg Config, clk clock.Clock, logger *slog.Logger) Service {
+	if repo == nil || events == nil {
+		panic("document repository and event buffer cannot be nil")
+	}
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+		logger.Warn("Warning: No logger provided to document.NewService, using default stderr handler")
+	}
+	if cfg.LinkTTL <= 0 {
+		cfg.LinkTTL = DefaultLinkTTL
+	}
+	if cfg.UploadLinkTTL <= 0 {
+		cfg.UploadLinkTTL = DefaultUploadLinkTTL
+	}
+	if cfg.Lookback <= 0 {
+		cfg.Lookback = DefaultLookback
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultBatchSize
+	}
+	return &service{
+		repo:     repo,
+		store:    store,
+		events:   events,
+		renderer: newRenderer(cfg.Location, cfg.Currency, cfg.MinorUnitDigits),
+		cfg:      cfg,
+		clock:    clock.OrSystem(clk),
+		logger:   logger.With(slog.String("component", "documentService")),
+	}
+}
+
+// IssuePayoffCertificates goes on past a loan it fails for and returns an
+// error only when the loans cannot be listed. A loan without a customer gets
+// its certificate but no event, since there is nobody to send it to.
+func (s *service) IssuePayoffCertificates(ctx context.Context) (*IssueReport, error) {
+	if s.store == nil {
+		return nil, fmt.Errorf("%w: document storage is not configured", apperrors.ErrUnavailable)
+	}
+	now := s.clock.Now()
+	loans, err := s.repo.ListUncertified(ctx, now.Add(-s.cfg.Lookback), s.cfg.BatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list loans awaiting a payoff certificate: %w", err)
+	}
+
+	report := &IssueReport{}
+	for _, l := range loans {
+		d, err := s.issuePayoffCertificate(ctx, l, now)
+		if errors.Is(err, apperrors.ErrConflict) {
+			continue
+		}
+		if err != nil {
+			s.logger.ErrorContext(ctx, "Failed to issue payoff certificate", slog.Int64("loanID", l.LoanID), slog.Any("error", err))
+			report.Failed++
+			continue
+		}
+		report.Issued = append(report.Issued, *d)
+		if l.CustomerID != nil {
+			s.events.Add(ctx, event.LoanPaidOffMessage(event.LoanPaidOffEvent{
+				LoanID:      l.LoanID,
+				CustomerID:  *l.CustomerID,
+				DocumentID:  d.ID,
+				DocumentURL: s.downloadLink(ctx, d),
+				Timestamp:   now,
+			}))
+		}
+	}
+	s.logger.InfoContext(ctx, "Payoff certificates issued", slog.Int("issued", len(report.Issued)), slog.Int("failed", report.Failed))
+	return report, nil
+}
+
+// issuePayoffCertificate returns ErrConflict when another run recorded the
+// loan's certificate first.
+func (s *service) issuePayoffCertificate(ctx context.Context, l PaidOffLoan, issuedAt time.Time) (*Document, error) {
+	content, err := s.renderer.payoffCertificate(l, issuedAt)
+	if err != nil {
+		return nil, err
+	}
+	d, err := s.storeGenerated(ctx, LoanSubject(l.LoanID), TypePayoffCertificate, payoffCertificateFileName(l.LoanID), content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to issue payoff certificate of loan %d: %w", l.LoanID, err)
+	}
+	s.logger.InfoContext(ctx, "Payoff certificate issued", slog.Int64("loanID", l.LoanID), slog.Int64("documentID", d.ID))
+	return d, nil
+}
+
+func (s *service) IssueLoanAgreement(ctx context.Context, agreement LoanAgreement) (*Document, []byte, error) {
+	if s.store == nil {
+		return nil, nil, fmt.Errorf("%w: document storage is not configured", apperrors.ErrUnavailable)
+	}
+	content, err := s.renderer.loanAgreement(agreement, s.clock.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+	d, err := s.storeGenerated(ctx, LoanSubject(agreement.LoanID), TypeContract, loanAgreementFileName(agreement.LoanID), content)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to issue agreement of loan %d: %w", agreement.LoanID, err)
+	}
+	s.logger.InfoContext(ctx, "Loan agreement issued", slog.Int64("loanID", agreement.LoanID), slog.Int64("documentID", d.ID))
+	return d, content, nil
+}
+
+// storeGenerated stores a PDF the engine generated before it records it.
+// If the record cannot be saved, including when another run recorded the
+// same payoff certificate first, the stored object is removed again.
+func (s *service) storeGenerated(ctx context.Context, subject Subject, t Type, fileName string, content []byte) (*Document, error) {
+	d := &Document{
+		Subject:     subject,
+		Type:        t,
+		Status:      StatusAvailable,
+		FileName:    fileName,
+		ContentType: ContentTypePDF,
+		SizeBytes:   int64(len(content)),
+		StorageKey:  storageKey(subject, uploadContentTypes[ContentTypePDF]),
+	}
+	if err := s.store.Put(ctx, d.StorageKey, d.ContentType, bytes.NewReader(content), d.SizeBytes); err != nil {
+		return nil, fmt.Errorf("%w: failed to store %s: %v", apperrors.ErrInternalServer, fileName, err)
+	}
+	if err := s.repo.Create(ctx, d); err != nil {
+		if delErr := s.store.Delete(context.WithoutCancel(ctx), d.StorageKey); delErr != nil {
+			s.logger.ErrorContext(ctx, "Failed to remove unrecorded document", slog.String("key", d.StorageKey), slog.Any("error", delErr))
+		}
+		return nil, err
+	}
+	return d, nil
+}
+
+// downloadLink is empty when the link cannot be signed; the event still
+// goes out and the document can be downloaded through the API.
+func (s *service) downloadLink(ctx context.Context, d *Document) string {
+	link, err := s.store.PresignGet(d.StorageKey, s.cfg.LinkTTL)
+	if err != nil {
+		s.logger.WarnContext(ctx, "Failed to sign document download link", slog.Int64("documentID", d.ID), slog.Any("error", err))
+		return ""
+	}
+	return link
+}
+
+// RequestUpload signs the upload link before it records the document, so
+// that a document is only recorded once it can be uploaded. An upload that
+// is never confirmed stays pending until the retention run removes it.
+func (s *service) RequestUpload(ctx context.Context, subject Subject, upload Upload) (*PendingUpload, error) {
+	if s.store == nil {
+		return nil, fmt.Errorf("%w: document storage is not configured", apperrors.ErrUnavailable)
+	}
+	if err := subject.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err)
+	}
+	d, err := s.newUpload(subject, upload)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err)
+	}
+
+	link, err := s.store.PresignPut(d.StorageKey, d.ContentType, s.cfg.UploadLinkTTL)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed to sign document upload link", slog.String("key", d.StorageKey), slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to sign upload link: %v", apperrors.ErrInternalServer, err)
+	}
+	expiresAt := s.clock.Now().Add(s.cfg.UploadLinkTTL)
+	if err := s.repo.Create(ctx, d); err != nil {
+		return nil, fmt.Errorf("failed to record %s upload for %s: %w", d.Type, subject, err)
+	}
+
+	s.logger.InfoContext(ctx, "Document upload requested", slog.String("subject", subject.String()), slog.Int64("documentID", d.ID), slog.String("type", string(d.Type)))
+	return &PendingUpload{Document: *d, URL: link, ExpiresAt: expiresAt}, nil
+}
+
+// newUpload checks that upload may be filed under subject and builds its
+// pending document.
+func (s *service) newUpload(subject Subject, upload Upload) (*Document, error) {
+	want, ok := uploadSubjects[upload.Type]
+	if !ok {
+		return nil, fmt.Errorf("documents of type %q cannot be uploaded", upload.Type)
+	}
+	if want != subject.Type {
+		return nil, fmt.Errorf("%s documents are uploaded to a %s, not a %s", upload.Type, want, subject.Type)
+	}
+	fileName, err := cleanFileName(upload.FileName)
+	if err != nil {
+		return nil, err
+	}
+	contentType, _, err := mime.ParseMediaType(upload.ContentType)
+	if err != nil {
+		return nil, fmt.Errorf("invalid content type %q", upload.ContentType)
+	}
+	extension, ok := uploadContentTypes[contentType]
+	if !ok {
+		return nil, fmt.Errorf("content type %s is not accepted, use application/pdf, image/jpeg or image/png", contentType)
+	}
+	switch {
+	case upload.SizeBytes <= 0:
+		return nil, fmt.Errorf("file cannot be empty")
+	case s.cfg.MaxUploadBytes > 0 && upload.SizeBytes > s.cfg.MaxUploadBytes:
+		return nil, fmt.Errorf("file exceeds %d bytes", s.cfg.MaxUploadBytes)
+	}
+	return &Document{
+		Subject:     subject,
+		Type:        upload.Type,
+		Status:      StatusPending,
+		FileName:    fileName,
+		ContentType: contentType,
+		SizeBytes:   upload.SizeBytes,
+		StorageKey:  storageKey(subject, extension),
+		UploadedBy:  strings.TrimSpace(upload.UploadedBy),
+	}, nil
+}
+
+// ConfirmUpload returns an available document as it is, so that a client
+// retrying its confirmation gets the same answer.
+func (s *service) ConfirmUpload(ctx context.Context, subject Subject, documentID int64) (*Document, error) {
+	if s.store == nil {
+		return nil, fmt.Errorf("%w: document storage is not configured", apperrors.ErrUnavailable)
+	}
+	if err := validateIDs(subject, documentID); err != nil {
+		return nil, err
+	}
+	d, err := s.repo.Get(ctx, subject, documentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document %d of %s: %w", documentID, subject, err)
+	}
+	if d.Status == StatusAvailable {
+		return d, nil
+	}
+
+	size, err := s.store.Size(ctx, d.StorageKey)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return nil, fmt.Errorf("%w: the file of document %d has not been uploaded yet", apperrors.ErrConflict, documentID)
+	}
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed to look up uploaded document", slog.String("key", d.StorageKey), slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to look up the file of document %d: %v", apperrors.ErrInternalServer, documentID, err)
+	}
+	if size != d.SizeBytes {
+		return nil, fmt.Errorf("%w: the uploaded file is %d bytes, but %d were announced; upload it again", apperrors.ErrConflict, size, d.SizeBytes)
+	}
+	if err := s.repo.MarkAvailable(ctx, subject, documentID); err != nil {
+		return nil, fmt.Errorf("failed to confirm document %d of %s: %w", documentID, subject, err)
+	}
+	d.Status = StatusAvailable
+
+	s.logger.InfoContext(ctx, "Document upload confirmed", slog.String("subject", subject.String()), slog.Int64("documentID", documentID))
+	return d, nil
+}
+
+func (s *service) List(ctx context.Context, subject Subject) ([]Document, error) {
+	if err := subject.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err)
+	}
+	documents, err := s.repo.List(ctx, subject)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents of %s: %w", subject, err)
+	}
+	if len(documents) == 0 {
+		exists, err := s.repo.SubjectExists(ctx, subject)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check %s: %w", subject, err)
+		}
+		if !exists {
+			return nil, fmt.Errorf("%w: %s", apperrors.ErrNotFound, subject)
+		}
+	}
+	return documents, nil
+}
+
+func (s *service) Open(ctx context.Context, subject Subject, documentID int64) (*Document, io.ReadCloser, error) {
+	if s.store == nil {
+		return nil, nil, fmt.Errorf("%w: document storage is not configured", apperrors.ErrUnavailable)
+	}
+	if err := validateIDs(subject, documentID); err != nil {
+		return nil, nil, err
+	}
+	d, err := s.repo.Get(ctx, subject, documentID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get document %d of %s: %w", documentID, subject, err)
+	}
+	if d.Status != StatusAvailable {
+		return nil, nil, fmt.Errorf("%w: the file of document %d has not been uploaded yet", apperrors.ErrConflict, documentID)
+	}
+	content, err := s.store.Get(ctx, d.StorageKey)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed to read document content", slog.Int64("documentID", documentID), slog.String("key", d.StorageKey), slog.Any("error", err))
+		if errors.Is(err, apperrors.ErrNotFound) {
+			return nil, nil, fmt.Errorf("%w: the content of document %d is missing from storage", apperrors.ErrInternalServer, documentID)
+		}
+		return nil, nil, fmt.Errorf("%w: failed to read document %d: %v", apperrors.ErrInternalServer, documentID, err)
+	}
+	return d, content, nil
+}
+
+// Delete removes the record before the content, like attachments: a failed
+// object delete leaves an unreferenced object behind, which is logged but
+// not reported to the caller. Payoff certificates are issued once and kept.
+func (s *service) Delete(ctx context.Context, subject Subject, documentID int64) error {
+	if s.store == nil {
+		return fmt.Errorf("%w: document storage is not configured", apperrors.ErrUnavailable)
+	}
+	if err := validateIDs(subject, documentID); err != nil {
+		return err
+	}
+	d, err := s.repo.Get(ctx, subject, documentID)
+	if err != nil {
+		return fmt.Errorf("failed to get document %d of %s: %w", documentID, subject, err)
+	}
+	if d.Type == TypePayoffCertificate {
+		return fmt.Errorf("%w: a payoff certificate cannot be deleted", apperrors.ErrConflict)
+	}
+	if err := s.repo.Delete(ctx, subject, documentID); err != nil {
+		return fmt.Errorf("failed to delete document %d of %s: %w", documentID, subject, err)
+	}
+	if err := s.store.Delete(ctx, d.StorageKey); err != nil {
+		s.logger.WarnContext(ctx, "Document deleted but its object could not be removed", slog.String("key", d.StorageKey), slog.Any("error", err))
+	}
+
+	s.logger.InfoContext(ctx, "Document deleted", slog.String("subject", subject.String()), slog.Int64("documentID", documentID))
+	return nil
+}
+
+// ApplyRetention goes on past a document it fails to remove and returns an
+// error only when documents cannot be listed.
+func (s *service) ApplyRetention(ctx context.Context) (*RetentionReport, error) {
+	if s.store == nil {
+		return nil, fmt.Errorf("%w: document storage is not configured", apperrors.ErrUnavailable)
+	}
+	now := s.clock.Now()
+	report := &RetentionReport{}
+	for _, t := range s.cfg.Retention.types() {
+		expired, err := s.repo.ListExpired(ctx, t, now.Add(-s.cfg.Retention.Periods[t]), s.cfg.BatchSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list expired %s documents: %w", t, err)
+		}
+		for i := range expired {
+			if s.remove(ctx, &expired[i]) {
+				report.Expired++
+			} else {
+				report.Failed++
+			}
+		}
+	}
+
+	abandoned, err := s.repo.ListStalePending(ctx, now.Add(-s.cfg.UploadLinkTTL-pendingUploadGrace), s.cfg.BatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list abandoned uploads: %w", err)
+	}
+	for i := range abandoned {
+		if s.remove(ctx, &abandoned[i]) {
+			report.Abandoned++
+		} else {
+			report.Failed++
+		}
+	}
+
+	s.logger.InfoContext(ctx, "Document retention applied", slog.Int("expired", report.Expired), slog.Int("abandoned", report.Abandoned), slog.Int("failed", report.Failed))
+	return report, nil
+}
+
+// remove deletes the content before the record, the other way round from
+// Delete, so that a document whose content could not be removed is still
+// listed and the next run tries again. It reports whether d is gone.
+func (s *service) remove(ctx context.Context, d *Document) bool {
+	if err := s.store.Delete(ctx, d.StorageKey); err != nil {
+		s.logger.ErrorContext(ctx, "Failed to remove document content", slog.Int64("documentID", d.ID), slog.String("key", d.StorageKey), slog.Any("error", err))
+		return false
+	}
+	if err := s.repo.Delete(ctx, d.Subject, d.ID); err != nil && !errors.Is(err, apperrors.ErrNotFound) {
+		s.logger.ErrorContext(ctx, "Failed to remove document record", slog.Int64("documentID", d.ID), slog.Any("error", err))
+		return false
+	}
+	s.logger.InfoContext(ctx, "Document removed by retention", slog.String("subject", d.Subject.String()), slog.Int64("documentID", d.ID), slog.String("type", string(d.Type)))
+	return true
+}
+
+func validateIDs(subject Subject, documentID int64) error {
+	if err := subject.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err)
+	}
+	if documentID <= 0 {
+		return fmt.Errorf("%w: document ID must be a positive number", apperrors.ErrInvalidArgument)
+	}
+	return nil
+}
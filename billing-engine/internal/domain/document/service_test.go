@@ -0,0 +1,466 @@
+package document
+
+import (
+	"billing-engine/internal/event"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+var testNow = time.Date(2025, 3, 3, 1, 0, 0, 0, time.UTC)
+
+// capturePublisher keeps the batches it is handed.
+type capturePublisher struct {
+	messages []event.Message
+}
+
+func (p *capturePublisher) PublishCustomerDelinquencyChanged(context.Context, event.CustomerDelinquencyChangedEvent) error {
+	return nil
+}
+
+func (p *capturePublisher) PublishCustomerCreated(context.Context, event.CustomerCreatedEvent) error {
+	return nil
+}
+
+func (p *capturePublisher) PublishCustomerUpdated(context.Context, event.CustomerUpdatedEvent) error {
+	return nil
+}
+
+func (p *capturePublisher) PublishBatch(_ context.Context, messages []event.Message) error {
+	p.messages = append(p.messages, messages...)
+	return nil
+}
+
+func newTestService(repo *MockRepository, store ObjectStore) (Service, *event.Buffer, *capturePublisher) {
+	pub := &capturePublisher{}
+	buffer := event.NewBuffer(pub, 100, time.Hour, testLogger)
+	return NewService(repo, store, buffer, Config{Currency: "IDR", MinorUnitDigits: 2}, clock.NewFake(testNow), testLogger), buffer, pub
+}
+
+func paidOffLoan(loanID int64, customerID *int64) PaidOffLoan {
+	return PaidOffLoan{
+		LoanID: loanID, PublicID: uuid.New(), CustomerID: customerID, CustomerName: "Budi",
+		Principal: 5_000_000, TotalRepaid: 5_500_000, StartDate: testNow.AddDate(0, -6, 0), PaidOffAt: testNow.Add(-time.Hour),
+	}
+}
+
+func TestServiceIssuePayoffCertificates(t *testing.T) {
+	ctx := context.Background()
+	since := testNow.Add(-DefaultLookback)
+	customerID := int64(9)
+
+	t.Run("stores, records and announces each certificate", func(t *testing.T) {
+		repo, store := new(MockRepository), new(MockObjectStore)
+		repo.On("ListUncertified", ctx, since, DefaultBatchSize).Return([]PaidOffLoan{paidOffLoan(42, &customerID), paidOffLoan(43, nil)}, nil)
+		var stored []string
+		store.On("Put", ctx, mock.AnythingOfType("string"), ContentTypePDF, mock.Anything, mock.AnythingOfType("int64")).
+			Run(func(args mock.Arguments) {
+				key := args.String(1)
+				content, _ := io.ReadAll(args.Get(3).(io.Reader))
+				assert.True(t, bytes.HasPrefix(content, []byte("%PDF-")))
+				assert.Equal(t, int64(len(content)), args.Get(4))
+				stored = append(stored, key)
+			}).Return(nil)
+		repo.On("Create", ctx, mock.MatchedBy(func(d *Document) bool {
+			d.ID = d.Subject.ID + 100
+			return d.Subject.Type == SubjectLoan && d.Type == TypePayoffCertificate && d.Status == StatusAvailable &&
+				strings.HasPrefix(d.StorageKey, "loans/") && d.FileName == payoffCertificateFileName(d.Subject.ID)
+		})).Return(nil)
+		store.On("PresignGet", mock.AnythingOfType("string"), DefaultLinkTTL).Return("https://bucket.example/cert?sig=1", nil)
+		svc, buffer, pub := newTestService(repo, store)
+
+		report, err := svc.IssuePayoffCertificates(ctx)
+
+		require.NoError(t, err)
+		require.Len(t, report.Issued, 2)
+		assert.Zero(t, report.Failed)
+		assert.Equal(t, stored[0], report.Issued[0].StorageKey)
+		require.NoError(t, buffer.Flush(ctx))
+		require.Len(t, pub.messages, 1, "the loan without a customer gets no event")
+		assert.Equal(t, event.TypeLoanPaidOff, pub.messages[0].Type)
+		assert.Equal(t, event.LoanPaidOffEvent{
+			EventID: pub.messages[0].EventID, LoanID: 42, CustomerID: 9, DocumentID: 142,
+			DocumentURL: "https://bucket.example/cert?sig=1", Timestamp: testNow,
+		}, pub.messages[0].Payload)
+		repo.AssertExpectations(t)
+		store.AssertNumberOfCalls(t, "PresignGet", 1)
+	})
+
+	t.Run("removes the stored copy when another run recorded one first", func(t *testing.T) {
+		repo, store := new(MockRepository), new(MockObjectStore)
+		repo.On("ListUncertified", ctx, since, DefaultBatchSize).Return([]PaidOffLoan{paidOffLoan(42, &customerID)}, nil)
+		store.On("Put", ctx, mock.Anything, ContentTypePDF, mock.Anything, mock.Anything).Return(nil)
+		repo.On("Create", ctx, mock.Anything).Return(apperrors.ErrConflict)
+		store.On("Delete", mock.Anything, mock.AnythingOfType("string")).Return(nil)
+		svc, buffer, pub := newTestService(repo, store)
+
+		report, err := svc.IssuePayoffCertificates(ctx)
+
+		require.NoError(t, err)
+		assert.Equal(t, &IssueReport{}, report)
+		store.AssertExpectations(t)
+		require.NoError(t, buffer.Flush(ctx))
+		assert.Empty(t, pub.messages)
+	})
+
+	t.Run("counts a loan it cannot store and goes on", func(t *testing.T) {
+		repo, store := new(MockRepository), new(MockObjectStore)
+		repo.On("ListUncertified", ctx, since, DefaultBatchSize).Return([]PaidOffLoan{paidOffLoan(42, &customerID), paidOffLoan(43, &customerID)}, nil)
+		store.On("Put", ctx, mock.MatchedBy(func(key string) bool { return strings.HasPrefix(key, "loans/42/") }), ContentTypePDF, mock.Anything, mock.Anything).
+			Return(errors.New("connection reset"))
+		store.On("Put", ctx, mock.MatchedBy(func(key string) bool { return strings.HasPrefix(key, "loans/43/") }), ContentTypePDF, mock.Anything, mock.Anything).
+			Return(nil)
+		repo.On("Create", ctx, mock.Anything).Return(nil)
+		store.On("PresignGet", mock.Anything, DefaultLinkTTL).Return("", errors.New("no credentials"))
+		svc, buffer, pub := newTestService(repo, store)
+
+		report, err := svc.IssuePayoffCertificates(ctx)
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, report.Failed)
+		require.Len(t, report.Issued, 1)
+		assert.Equal(t, int64(43), report.Issued[0].Subject.ID)
+		require.NoError(t, buffer.Flush(ctx))
+		require.Len(t, pub.messages, 1)
+		assert.Empty(t, pub.messages[0].Payload.(event.LoanPaidOffEvent).DocumentURL, "the event goes out without a link")
+	})
+
+	t.Run("needs object storage", func(t *testing.T) {
+		svc, _, _ := newTestService(new(MockRepository), nil)
+
+		_, err := svc.IssuePayoffCertificates(ctx)
+
+		assert.ErrorIs(t, err, apperrors.ErrUnavailable)
+	})
+}
+
+func TestServiceIssueLoanAgreement(t *testing.T) {
+	ctx := context.Background()
+	agreement := LoanAgreement{LoanID: 42, PublicID: uuid.New(), CustomerName: "Budi", Principal: 5_000_000, AnnualInterestRate: 0.1, TermWeeks: 50, WeeklyInstallment: 110_000, TotalAmount: 5_500_000}
+
+	t.Run("stores and records the agreement as a contract of the loan", func(t *testing.T) {
+		repo, store := new(MockRepository), new(MockObjectStore)
+		var stored []byte
+		store.On("Put", ctx, mock.MatchedBy(func(key string) bool { return strings.HasPrefix(key, "loans/42/documents/") }), ContentTypePDF, mock.Anything, mock.AnythingOfType("int64")).
+			Run(func(args mock.Arguments) { stored, _ = io.ReadAll(args.Get(3).(io.Reader)) }).Return(nil)
+		repo.On("Create", ctx, mock.MatchedBy(func(d *Document) bool {
+			d.ID = 7
+			return d.Subject == LoanSubject(42) && d.Type == TypeContract && d.Status == StatusAvailable &&
+				d.FileName == "loan-agreement-42.pdf" && d.UploadedBy == ""
+		})).Return(nil)
+		svc, _, _ := newTestService(repo, store)
+
+		d, content, err := svc.IssueLoanAgreement(ctx, agreement)
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), d.ID)
+		assert.Equal(t, stored, content)
+		assert.Equal(t, int64(len(content)), d.SizeBytes)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("removes the stored copy when the record fails", func(t *testing.T) {
+		repo, store := new(MockRepository), new(MockObjectStore)
+		store.On("Put", ctx, mock.Anything, ContentTypePDF, mock.Anything, mock.Anything).Return(nil)
+		repo.On("Create", ctx, mock.Anything).Return(fmt.Errorf("%w: loan 42", apperrors.ErrNotFound))
+		store.On("Delete", mock.Anything, mock.AnythingOfType("string")).Return(nil)
+		svc, _, _ := newTestService(repo, store)
+
+		_, _, err := svc.IssueLoanAgreement(ctx, agreement)
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+		store.AssertExpectations(t)
+	})
+
+	t.Run("needs object storage", func(t *testing.T) {
+		svc, _, _ := newTestService(new(MockRepository), nil)
+
+		_, _, err := svc.IssueLoanAgreement(ctx, agreement)
+
+		assert.ErrorIs(t, err, apperrors.ErrUnavailable)
+	})
+}
+
+func TestServiceList(t *testing.T) {
+	ctx := context.Background()
+	loan := LoanSubject(42)
+
+	t.Run("lists the loan's documents", func(t *testing.T) {
+		repo := new(MockRepository)
+		repo.On("List", ctx, loan).Return([]Document{{ID: 1, Subject: loan}}, nil)
+		svc, _, _ := newTestService(repo, nil)
+
+		documents, err := svc.List(ctx, loan)
+
+		require.NoError(t, err)
+		assert.Len(t, documents, 1)
+		repo.AssertNotCalled(t, "SubjectExists", mock.Anything, mock.Anything)
+	})
+
+	t.Run("reports an unknown customer", func(t *testing.T) {
+		customer := Subject{Type: SubjectCustomer, ID: 7}
+		repo := new(MockRepository)
+		repo.On("List", ctx, customer).Return([]Document{}, nil)
+		repo.On("SubjectExists", ctx, customer).Return(false, nil)
+		svc, _, _ := newTestService(repo, nil)
+
+		_, err := svc.List(ctx, customer)
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+}
+
+func TestServiceOpen(t *testing.T) {
+	ctx := context.Background()
+	loan := LoanSubject(42)
+	d := &Document{ID: 5, Subject: loan, Status: StatusAvailable, StorageKey: "loans/42/documents/key.pdf"}
+
+	t.Run("returns the stored content", func(t *testing.T) {
+		repo, store := new(MockRepository), new(MockObjectStore)
+		repo.On("Get", ctx, loan, int64(5)).Return(d, nil)
+		store.On("Get", ctx, d.StorageKey).Return(io.NopCloser(strings.NewReader("%PDF-1.4")), nil)
+		svc, _, _ := newTestService(repo, store)
+
+		got, content, err := svc.Open(ctx, loan, 5)
+
+		require.NoError(t, err)
+		defer content.Close()
+		assert.Equal(t, d, got)
+		body, _ := io.ReadAll(content)
+		assert.Equal(t, "%PDF-1.4", string(body))
+	})
+
+	t.Run("treats missing content as an internal error", func(t *testing.T) {
+		repo, store := new(MockRepository), new(MockObjectStore)
+		repo.On("Get", ctx, loan, int64(5)).Return(d, nil)
+		store.On("Get", ctx, d.StorageKey).Return(nil, apperrors.ErrNotFound)
+		svc, _, _ := newTestService(repo, store)
+
+		_, _, err := svc.Open(ctx, loan, 5)
+
+		assert.ErrorIs(t, err, apperrors.ErrInternalServer)
+		assert.NotErrorIs(t, err, apperrors.ErrNotFound)
+	})
+
+	t.Run("passes on an unknown document", func(t *testing.T) {
+		repo := new(MockRepository)
+		repo.On("Get", ctx, loan, int64(6)).Return(nil, apperrors.ErrNotFound)
+		svc, _, _ := newTestService(repo, new(MockObjectStore))
+
+		_, _, err := svc.Open(ctx, loan, 6)
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+
+	t.Run("refuses an upload that was not confirmed", func(t *testing.T) {
+		repo, store := new(MockRepository), new(MockObjectStore)
+		repo.On("Get", ctx, loan, int64(7)).Return(&Document{ID: 7, Subject: loan, Status: StatusPending}, nil)
+		svc, _, _ := newTestService(repo, store)
+
+		_, _, err := svc.Open(ctx, loan, 7)
+
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+		store.AssertNotCalled(t, "Get", mock.Anything, mock.Anything)
+	})
+}
+
+// newUploadService allows uploads of up to 1 MiB and keeps ID copies for a
+// year.
+func newUploadService(repo *MockRepository, store ObjectStore) Service {
+	cfg := Config{
+		MaxUploadBytes: 1 << 20,
+		Retention:      RetentionPolicy{Periods: map[Type]time.Duration{TypeIDCopy: 365 * 24 * time.Hour}},
+	}
+	return NewService(repo, store, event.NewBuffer(&capturePublisher{}, 100, time.Hour, testLogger), cfg, clock.NewFake(testNow), testLogger)
+}
+
+func TestServiceRequestUpload(t *testing.T) {
+	ctx := context.Background()
+	customer := Subject{Type: SubjectCustomer, ID: 7}
+	upload := Upload{Type: TypeIDCopy, FileName: `C:\scans\ktp.png`, ContentType: "image/png", SizeBytes: 2048, UploadedBy: " agent "}
+
+	t.Run("records a pending document and signs its upload", func(t *testing.T) {
+		repo, store := new(MockRepository), new(MockObjectStore)
+		store.On("PresignPut", mock.MatchedBy(func(key string) bool {
+			return strings.HasPrefix(key, "customers/7/documents/") && strings.HasSuffix(key, ".png")
+		}), "image/png", DefaultUploadLinkTTL).Return("https://bucket.example/put?sig=1", nil)
+		repo.On("Create", ctx, mock.MatchedBy(func(d *Document) bool {
+			d.ID = 11
+			return d.Subject == customer && d.Status == StatusPending && d.FileName == "ktp.png" && d.UploadedBy == "agent"
+		})).Return(nil)
+
+		pending, err := newUploadService(repo, store).RequestUpload(ctx, customer, upload)
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(11), pending.Document.ID)
+		assert.Equal(t, "https://bucket.example/put?sig=1", pending.URL)
+		assert.Equal(t, testNow.Add(DefaultUploadLinkTTL), pending.ExpiresAt)
+		repo.AssertExpectations(t)
+	})
+
+	for name, tc := range map[string]struct {
+		subject Subject
+		upload  func(u *Upload)
+	}{
+		"a payoff certificate":       {customer, func(u *Upload) { u.Type = TypePayoffCertificate }},
+		"an ID copy on a loan":       {LoanSubject(42), func(u *Upload) {}},
+		"an unaccepted content type": {customer, func(u *Upload) { u.ContentType = "text/html" }},
+		"an empty file":              {customer, func(u *Upload) { u.SizeBytes = 0 }},
+		"an oversized file":          {customer, func(u *Upload) { u.SizeBytes = 1<<20 + 1 }},
+		"a missing file name":        {customer, func(u *Upload) { u.FileName = " " }},
+	} {
+		t.Run("refuses "+name, func(t *testing.T) {
+			repo, store := new(MockRepository), new(MockObjectStore)
+			u := upload
+			tc.upload(&u)
+
+			_, err := newUploadService(repo, store).RequestUpload(ctx, tc.subject, u)
+
+			assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+			repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+		})
+	}
+
+	t.Run("accepts a content type with parameters", func(t *testing.T) {
+		repo, store := new(MockRepository), new(MockObjectStore)
+		store.On("PresignPut", mock.Anything, ContentTypePDF, DefaultUploadLinkTTL).Return("https://bucket.example/put", nil)
+		repo.On("Create", ctx, mock.Anything).Return(nil)
+		u := upload
+		u.Type, u.ContentType = TypeContract, "Application/PDF; charset=binary"
+
+		pending, err := newUploadService(repo, store).RequestUpload(ctx, LoanSubject(42), u)
+
+		require.NoError(t, err)
+		assert.Equal(t, ContentTypePDF, pending.Document.ContentType)
+	})
+}
+
+func TestServiceConfirmUpload(t *testing.T) {
+	ctx := context.Background()
+	loan := LoanSubject(42)
+	pending := func() *Document {
+		return &Document{ID: 5, Subject: loan, Type: TypeContract, Status: StatusPending, SizeBytes: 2048, StorageKey: "loans/42/documents/key.pdf"}
+	}
+
+	t.Run("makes the uploaded file available", func(t *testing.T) {
+		repo, store := new(MockRepository), new(MockObjectStore)
+		repo.On("Get", ctx, loan, int64(5)).Return(pending(), nil)
+		store.On("Size", ctx, "loans/42/documents/key.pdf").Return(int64(2048), nil)
+		repo.On("MarkAvailable", ctx, loan, int64(5)).Return(nil)
+
+		d, err := newUploadService(repo, store).ConfirmUpload(ctx, loan, 5)
+
+		require.NoError(t, err)
+		assert.Equal(t, StatusAvailable, d.Status)
+	})
+
+	t.Run("answers a repeated confirmation the same way", func(t *testing.T) {
+		repo, store := new(MockRepository), new(MockObjectStore)
+		d := pending()
+		d.Status = StatusAvailable
+		repo.On("Get", ctx, loan, int64(5)).Return(d, nil)
+
+		got, err := newUploadService(repo, store).ConfirmUpload(ctx, loan, 5)
+
+		require.NoError(t, err)
+		assert.Equal(t, StatusAvailable, got.Status)
+		store.AssertNotCalled(t, "Size", mock.Anything, mock.Anything)
+	})
+
+	t.Run("reports a file that has not arrived", func(t *testing.T) {
+		repo, store := new(MockRepository), new(MockObjectStore)
+		repo.On("Get", ctx, loan, int64(5)).Return(pending(), nil)
+		store.On("Size", ctx, mock.Anything).Return(int64(0), apperrors.ErrNotFound)
+
+		_, err := newUploadService(repo, store).ConfirmUpload(ctx, loan, 5)
+
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+		repo.AssertNotCalled(t, "MarkAvailable", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("refuses a file of another size", func(t *testing.T) {
+		repo, store := new(MockRepository), new(MockObjectStore)
+		repo.On("Get", ctx, loan, int64(5)).Return(pending(), nil)
+		store.On("Size", ctx, mock.Anything).Return(int64(100), nil)
+
+		_, err := newUploadService(repo, store).ConfirmUpload(ctx, loan, 5)
+
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+		assert.ErrorContains(t, err, "100 bytes, but 2048")
+	})
+}
+
+func TestServiceDelete(t *testing.T) {
+	ctx := context.Background()
+	loan := LoanSubject(42)
+
+	t.Run("removes the record and the content", func(t *testing.T) {
+		repo, store := new(MockRepository), new(MockObjectStore)
+		repo.On("Get", ctx, loan, int64(5)).Return(&Document{ID: 5, Subject: loan, Type: TypeContract, StorageKey: "key"}, nil)
+		repo.On("Delete", ctx, loan, int64(5)).Return(nil)
+		store.On("Delete", ctx, "key").Return(errors.New("timeout"))
+
+		err := newUploadService(repo, store).Delete(ctx, loan, 5)
+
+		require.NoError(t, err, "content left behind is only logged")
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("keeps payoff certificates", func(t *testing.T) {
+		repo, store := new(MockRepository), new(MockObjectStore)
+		repo.On("Get", ctx, loan, int64(5)).Return(&Document{ID: 5, Subject: loan, Type: TypePayoffCertificate}, nil)
+
+		err := newUploadService(repo, store).Delete(ctx, loan, 5)
+
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+		repo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestServiceApplyRetention(t *testing.T) {
+	ctx := context.Background()
+	customer := Subject{Type: SubjectCustomer, ID: 7}
+
+	t.Run("removes expired documents and abandoned uploads", func(t *testing.T) {
+		repo, store := new(MockRepository), new(MockObjectStore)
+		repo.On("ListExpired", ctx, TypeIDCopy, testNow.Add(-365*24*time.Hour), DefaultBatchSize).Return([]Document{
+			{ID: 1, Subject: customer, StorageKey: "a"}, {ID: 2, Subject: customer, StorageKey: "b"},
+		}, nil)
+		repo.On("ListStalePending", ctx, testNow.Add(-DefaultUploadLinkTTL-pendingUploadGrace), DefaultBatchSize).Return([]Document{
+			{ID: 3, Subject: LoanSubject(42), StorageKey: "c"},
+		}, nil)
+		store.On("Delete", ctx, "a").Return(nil)
+		store.On("Delete", ctx, "b").Return(errors.New("timeout"))
+		store.On("Delete", ctx, "c").Return(nil)
+		repo.On("Delete", ctx, customer, int64(1)).Return(nil)
+		repo.On("Delete", ctx, LoanSubject(42), int64(3)).Return(apperrors.ErrNotFound)
+
+		report, err := newUploadService(repo, store).ApplyRetention(ctx)
+
+		require.NoError(t, err)
+		assert.Equal(t, &RetentionReport{Expired: 1, Abandoned: 1, Failed: 1}, report)
+		repo.AssertNotCalled(t, "Delete", ctx, customer, int64(2))
+		repo.AssertNotCalled(t, "ListExpired", mock.Anything, TypeContract, mock.Anything, mock.Anything)
+	})
+
+	t.Run("needs object storage", func(t *testing.T) {
+		_, err := newUploadService(new(MockRepository), nil).ApplyRetention(ctx)
+
+		assert.ErrorIs(t, err, apperrors.ErrUnavailable)
+	})
+}
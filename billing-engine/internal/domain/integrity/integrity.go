@@ -0,0 +1,108 @@
+package integrity
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"fmt"
+	"time"
+)
+
+// Check names an invariant of the loan data. The database constraints should
+// make every one of them hold; a violation means a constraint is missing, was
+// bypassed or a write went wrong.
+type Check string
+
+const (
+	// CheckCustomerLoanMissing finds customers whose loan_id names a loan
+	// that does not exist.
+	CheckCustomerLoanMissing Check = "customer_loan_missing"
+	// CheckLedgerMismatch finds loans whose paid schedule amounts do not add
+	// up to the payments recorded for them.
+	CheckLedgerMismatch Check = "ledger_mismatch"
+	// CheckPaidOffUnpaid finds PAID_OFF loans with unpaid installments.
+	CheckPaidOffUnpaid Check = "paid_off_unpaid"
+	// CheckOrphanSchedule finds schedule entries of loans that do not exist.
+	CheckOrphanSchedule Check = "orphan_schedule"
+)
+
+// Checks lists every check in the order a run goes through them.
+var Checks = []Check{CheckCustomerLoanMissing, CheckLedgerMismatch, CheckPaidOffUnpaid, CheckOrphanSchedule}
+
+// ParseCheck validates a check name. It returns ErrInvalidArgument for a name
+// that is not one of Checks.
+func ParseCheck(name string) (Check, error) {
+	for _, c := range Checks {
+		if string(c) == name {
+			return c, nil
+		}
+	}
+	return "", fmt.Errorf("%w: unknown integrity check %q", apperrors.ErrInvalidArgument, name)
+}
+
+const (
+	EntityCustomer = "customer"
+	EntityLoan     = "loan"
+)
+
+// Finding is one violation of a check. A finding that is still there on the
+// next run keeps its FirstSeenAt; one that is gone is dropped.
+type Finding struct {
+	ID          int64
+	Check       Check
+	EntityType  string
+	EntityID    int64
+	Detail      string
+	FirstSeenAt time.Time
+	LastSeenAt  time.Time
+}
+
+func CustomerLoanMissing(customerID, loanID int64) Finding {
+	return Finding{
+		Check:      CheckCustomerLoanMissing,
+		EntityType: EntityCustomer,
+		EntityID:   customerID,
+		Detail:     fmt.Sprintf("customer references loan %d, which does not exist", loanID),
+	}
+}
+
+func LedgerMismatch(loanID int64, schedulePaid, ledgerPaid float64) Finding {
+	return Finding{
+		Check:      CheckLedgerMismatch,
+		EntityType: EntityLoan,
+		EntityID:   loanID,
+		Detail:     fmt.Sprintf("schedule shows %.2f paid but the payments add up to %.2f", schedulePaid, ledgerPaid),
+	}
+}
+
+func PaidOffUnpaid(loanID int64, unpaid int) Finding {
+	return Finding{
+		Check:      CheckPaidOffUnpaid,
+		EntityType: EntityLoan,
+		EntityID:   loanID,
+		Detail:     fmt.Sprintf("loan is PAID_OFF with %d unpaid installments", unpaid),
+	}
+}
+
+func OrphanSchedule(loanID int64, entries int) Finding {
+	return Finding{
+		Check:      CheckOrphanSchedule,
+		EntityType: EntityLoan,
+		EntityID:   loanID,
+		Detail:     fmt.Sprintf("%d schedule entries belong to a loan that does not exist", entries),
+	}
+}
+
+// Report sums up a run: the violations each check found, every check
+// included, and when the run happened.
+type Report struct {
+	RunAt      time.Time
+	Violations map[Check]int
+}
+
+// Total is the number of violations over all checks.
+func (r *Report) Total() int {
+	n := 0
+	for _, v := range r.Violations {
+		n += v
+	}
+	return n
+}
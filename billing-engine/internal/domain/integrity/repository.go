@@ -0,0 +1,24 @@
+package integrity
+
+import (
+	"context"
+	"time"
+)
+
+// Repository runs the checks against the database and keeps the findings of
+// the latest run in the integrity_findings table.
+type Repository interface {
+	// Detect runs one check and returns its violations, ordered by entity.
+	// The findings carry no ID or timestamps yet.
+	Detect(ctx context.Context, check Check) ([]Finding, error)
+
+	// ReplaceFindings makes findings the current set. A finding already
+	// stored for the same check and entity keeps its first_seen_at; stored
+	// findings that are not in the set are deleted. runAt stamps
+	// last_seen_at.
+	ReplaceFindings(ctx context.Context, runAt time.Time, findings []Finding) error
+
+	// ListFindings returns the stored findings of check, or of every check
+	// when check is empty, oldest first.
+	ListFindings(ctx context.Context, check Check) ([]Finding, error)
+}
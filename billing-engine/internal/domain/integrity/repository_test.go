@@ -0,0 +1,28 @@
+package integrity
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) Detect(ctx context.Context, check Check) ([]Finding, error) {
+	args := m.Called(ctx, check)
+	f, _ := args.Get(0).([]Finding)
+	return f, args.Error(1)
+}
+
+func (m *MockRepository) ReplaceFindings(ctx context.Context, runAt time.Time, findings []Finding) error {
+	return m.Called(ctx, runAt, findings).Error(0)
+}
+
+func (m *MockRepository) ListFindings(ctx context.Context, check Check) ([]Finding, error) {
+	args := m.Called(ctx, check)
+	f, _ := args.Get(0).([]Finding)
+	return f, args.Error(1)
+}
@@ -0,0 +1,85 @@
+package integrity
+
+import (
+	"billing-engine/internal/infrastructure/monitoring"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+type Service interface {
+	// Run goes through every check, stores what they found in place of the
+	// previous findings and updates the violation gauges. When a check
+	// fails nothing is stored, so the previous findings stay.
+	Run(ctx context.Context) (*Report, error)
+
+	// ListFindings returns the findings of the last run, only those of
+	// check unless it is empty. An unknown check is ErrInvalidArgument.
+	ListFindings(ctx context.Context, check string) ([]Finding, error)
+}
+
+var _ Service = (*service)(nil)
+
+type service struct {
+	repo   Repository
+	clock  clock.Clock
+	logger *slog.Logger
+}
+
+// NewService wires the integrity service. The clock stamps the findings and
+// nil means the wall clock.
+func NewService(repo Repository, clk clock.Clock, logger *slog.Logger) Service {
+	if repo == nil {
+		panic("integrity repository cannot be nil")
+	}
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+		logger.Warn("Warning: No logger provided to integrity.NewService, using default stderr handler")
+	}
+	return &service{
+		repo:   repo,
+		clock:  clock.OrSystem(clk),
+		logger: logger.With(slog.String("component", "integrityService")),
+	}
+}
+
+func (s *service) Run(ctx context.Context) (*Report, error) {
+	report := &Report{RunAt: s.clock.Now(), Violations: make(map[Check]int, len(Checks))}
+	var findings []Finding
+	for _, check := range Checks {
+		found, err := s.repo.Detect(ctx, check)
+		if err != nil {
+			return nil, fmt.Errorf("integrity check %s failed: %w", check, err)
+		}
+		report.Violations[check] = len(found)
+		findings = append(findings, found...)
+	}
+
+	if err := s.repo.ReplaceFindings(ctx, report.RunAt, findings); err != nil {
+		return nil, fmt.Errorf("failed to store integrity findings: %w", err)
+	}
+
+	gauges := make(map[string]int, len(report.Violations))
+	for check, n := range report.Violations {
+		gauges[string(check)] = n
+		if n > 0 {
+			s.logger.WarnContext(ctx, "Integrity check found violations", slog.String("check", string(check)), slog.Int("violations", n))
+		}
+	}
+	monitoring.SetIntegrityViolations(gauges)
+	return report, nil
+}
+
+func (s *service) ListFindings(ctx context.Context, check string) ([]Finding, error) {
+	var c Check
+	if check != "" {
+		parsed, err := ParseCheck(check)
+		if err != nil {
+			return nil, err
+		}
+		c = parsed
+	}
+	return s.repo.ListFindings(ctx, c)
+}
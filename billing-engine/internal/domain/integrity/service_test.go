@@ -0,0 +1,93 @@
+package integrity
+
+import (
+	"billing-engine/internal/infrastructure/monitoring"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	testNow    = time.Date(2025, 3, 10, 4, 15, 0, 0, time.UTC)
+)
+
+func newTestService(repo Repository) Service {
+	return NewService(repo, clock.NewFake(testNow), testLogger)
+}
+
+func TestServiceRun(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("stores the findings of every check and sets the gauges", func(t *testing.T) {
+		repo := new(MockRepository)
+		missing := CustomerLoanMissing(4, 99)
+		mismatch := []Finding{LedgerMismatch(7, 220, 110), LedgerMismatch(8, 110, 0)}
+		repo.On("Detect", ctx, CheckCustomerLoanMissing).Return([]Finding{missing}, nil).Once()
+		repo.On("Detect", ctx, CheckLedgerMismatch).Return(mismatch, nil).Once()
+		repo.On("Detect", ctx, CheckPaidOffUnpaid).Return(nil, nil).Once()
+		repo.On("Detect", ctx, CheckOrphanSchedule).Return(nil, nil).Once()
+		repo.On("ReplaceFindings", ctx, testNow, []Finding{missing, mismatch[0], mismatch[1]}).Return(nil).Once()
+
+		report, err := newTestService(repo).Run(ctx)
+
+		require.NoError(t, err)
+		assert.Equal(t, testNow, report.RunAt)
+		assert.Equal(t, 3, report.Total())
+		assert.Equal(t, 2, report.Violations[CheckLedgerMismatch])
+		assert.Equal(t, 0, report.Violations[CheckOrphanSchedule])
+		assert.Equal(t, 2.0, testutil.ToFloat64(monitoring.Integrity.Violations.WithLabelValues(string(CheckLedgerMismatch))))
+		assert.Equal(t, 0.0, testutil.ToFloat64(monitoring.Integrity.Violations.WithLabelValues(string(CheckPaidOffUnpaid))))
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("keeps the previous findings when a check fails", func(t *testing.T) {
+		repo := new(MockRepository)
+		repo.On("Detect", ctx, CheckCustomerLoanMissing).Return(nil, nil).Once()
+		repo.On("Detect", ctx, CheckLedgerMismatch).Return(nil, apperrors.ErrDatabase).Once()
+
+		_, err := newTestService(repo).Run(ctx)
+
+		assert.ErrorIs(t, err, apperrors.ErrDatabase)
+		repo.AssertNotCalled(t, "ReplaceFindings", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestServiceListFindings(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("lists every check", func(t *testing.T) {
+		repo := new(MockRepository)
+		repo.On("ListFindings", ctx, Check("")).Return([]Finding{OrphanSchedule(5, 3)}, nil).Once()
+
+		findings, err := newTestService(repo).ListFindings(ctx, "")
+
+		require.NoError(t, err)
+		assert.Len(t, findings, 1)
+	})
+
+	t.Run("filters by check", func(t *testing.T) {
+		repo := new(MockRepository)
+		repo.On("ListFindings", ctx, CheckPaidOffUnpaid).Return([]Finding{}, nil).Once()
+
+		_, err := newTestService(repo).ListFindings(ctx, "paid_off_unpaid")
+
+		require.NoError(t, err)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("rejects an unknown check", func(t *testing.T) {
+		_, err := newTestService(new(MockRepository)).ListFindings(ctx, "loans_are_fine")
+
+		assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+	})
+}
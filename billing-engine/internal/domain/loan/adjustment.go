@@ -0,0 +1,241 @@
+package loan
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+type AdjustmentKind string
+
+const (
+	// AdjustmentPaymentHoliday skips Weeks installments from StartsOn on:
+	// every installment due then or later falls due Weeks weeks later, which
+	// extends the term without adding installments or interest.
+	AdjustmentPaymentHoliday AdjustmentKind = "PAYMENT_HOLIDAY"
+	// AdjustmentZeroInterest waives the interest of the installments due in
+	// the Weeks weeks from StartsOn, which then repay principal only.
+	AdjustmentZeroInterest AdjustmentKind = "ZERO_INTEREST"
+)
+
+const (
+	maxAdjustmentWeeks        = 52
+	maxAdjustmentReasonLength = 500
+)
+
+// ScheduleAdjustment is a payment holiday or a promotional zero-interest
+// window on a loan. Removed adjustments no longer shape the schedule but are
+// kept, with who applied and removed them, as the loan's audit trail.
+type ScheduleAdjustment struct {
+	ID        int64
+	LoanID    int64
+	Kind      AdjustmentKind
+	StartsOn  time.Time
+	Weeks     int
+	Reason    string
+	AppliedBy *string
+	AppliedAt time.Time
+	RemovedBy *string
+	RemovedAt *time.Time
+}
+
+func (a *ScheduleAdjustment) active() bool {
+	return a.RemovedAt == nil
+}
+
+// endsOn is the first day after the adjustment.
+func (a *ScheduleAdjustment) endsOn() time.Time {
+	return truncateToDate(a.StartsOn).AddDate(0, 0, 7*a.Weeks)
+}
+
+func (a *ScheduleAdjustment) covers(day time.Time) bool {
+	day = truncateToDate(day)
+	return !day.Before(truncateToDate(a.StartsOn)) && day.Before(a.endsOn())
+}
+
+// AdjustmentPlan is an adjustment being applied or removed together with the
+// installments that move or change amount and the loan total that follows.
+type AdjustmentPlan struct {
+	Adjustment      ScheduleAdjustment
+	Changes         []ScheduleChange
+	TotalLoanAmount Money
+}
+
+// activeHolidays returns the loan's payment holidays in effect, earliest
+// first.
+func (l *Loan) activeHolidays() []ScheduleAdjustment {
+	var holidays []ScheduleAdjustment
+	for _, a := range l.Adjustments {
+		if a.active() && a.Kind == AdjustmentPaymentHoliday {
+			holidays = append(holidays, a)
+		}
+	}
+	slices.SortFunc(holidays, func(a, b ScheduleAdjustment) int { return a.StartsOn.Compare(b.StartsOn) })
+	return holidays
+}
+
+// dueDateOf is when installment week falls due once the loan's payment
+// holidays have pushed it back.
+func (l *Loan) dueDateOf(week int) time.Time {
+	due := l.StartDate.AddDate(0, 0, week*7)
+	for _, holiday := range l.activeHolidays() {
+		if !truncateToDate(due).Before(truncateToDate(holiday.StartsOn)) {
+			due = due.AddDate(0, 0, 7*holiday.Weeks)
+		}
+	}
+	return due
+}
+
+// chargedRateOn is the interest rate of an installment due on due: none
+// within a zero-interest window, the rate in effect then otherwise.
+func (l *Loan) chargedRateOn(due time.Time) float64 {
+	for i := range l.Adjustments {
+		if a := &l.Adjustments[i]; a.active() && a.Kind == AdjustmentZeroInterest && a.covers(due) {
+			return 0
+		}
+	}
+	return l.rateOn(due)
+}
+
+// totalFromTerms is what the loan costs: the principal plus, for every
+// week, the interest at the rate charged on its due date on the principal
+// the week repays.
+func (l *Loan) totalFromTerms() Money {
+	interest := 0.0
+	for week := 1; week <= l.TermWeeks; week++ {
+		interest += l.principalOf(week) * l.chargedRateOn(l.dueDateOf(week))
+	}
+	return roundTo(l.PrincipalAmount+interest, 2)
+}
+
+func validateAdjustment(a *ScheduleAdjustment) error {
+	switch a.Kind {
+	case AdjustmentPaymentHoliday, AdjustmentZeroInterest:
+	default:
+		return fmt.Errorf("%w: adjustment kind must be %s or %s", apperrors.ErrInvalidArgument, AdjustmentPaymentHoliday, AdjustmentZeroInterest)
+	}
+	if a.Weeks < 1 || a.Weeks > maxAdjustmentWeeks {
+		return fmt.Errorf("%w: an adjustment lasts 1 to %d weeks", apperrors.ErrInvalidArgument, maxAdjustmentWeeks)
+	}
+	if a.StartsOn.IsZero() {
+		return fmt.Errorf("%w: start date is required", apperrors.ErrInvalidArgument)
+	}
+	a.Reason = strings.TrimSpace(a.Reason)
+	if a.Reason == "" {
+		return fmt.Errorf("%w: an adjustment needs a reason", apperrors.ErrInvalidArgument)
+	}
+	if len(a.Reason) > maxAdjustmentReasonLength {
+		return fmt.Errorf("%w: adjustment reason must be at most %d characters", apperrors.ErrInvalidArgument, maxAdjustmentReasonLength)
+	}
+	return nil
+}
+
+// PlanScheduleAdjustment works out what applying adjustment does to l, whose
+// Adjustments and RateHistory must be complete, and to current, its stored
+// schedule. today is the billing date. An adjustment starts today at the
+// earliest, on a loan that is not paid off, and may not overlap another of
+// its kind. Payment holidays are only granted to loans that are not
+// delinquent, since moving overdue installments would hide the arrears.
+func PlanScheduleAdjustment(l *Loan, current []ScheduleEntry, adjustment ScheduleAdjustment, today time.Time) (*AdjustmentPlan, error) {
+	if err := validateAdjustment(&adjustment); err != nil {
+		return nil, err
+	}
+	adjustment.LoanID = l.ID
+	adjustment.StartsOn = truncateToDate(adjustment.StartsOn)
+	if adjustment.StartsOn.Before(truncateToDate(today)) {
+		return nil, fmt.Errorf("%w: an adjustment cannot start before today", apperrors.ErrInvalidArgument)
+	}
+	switch {
+	case l.Status == StatusPaidOff:
+		return nil, fmt.Errorf("%w: loan %d is paid off", apperrors.ErrConflict, l.ID)
+	case l.Status == StatusDelinquent && adjustment.Kind == AdjustmentPaymentHoliday:
+		return nil, fmt.Errorf("%w: loan %d is delinquent; a payment holiday needs the arrears paid first", apperrors.ErrConflict, l.ID)
+	}
+	if err := l.checkReshapable("a " + string(adjustment.Kind)); err != nil {
+		return nil, err
+	}
+	for i := range l.Adjustments {
+		other := &l.Adjustments[i]
+		if other.active() && other.Kind == adjustment.Kind &&
+			adjustment.StartsOn.Before(other.endsOn()) && truncateToDate(other.StartsOn).Before(adjustment.endsOn()) {
+			return nil, fmt.Errorf("%w: loan %d already has a %s from %s", apperrors.ErrConflict, l.ID, other.Kind, other.StartsOn.Format("2006-01-02"))
+		}
+	}
+
+	adjusted := *l
+	adjusted.Adjustments = append(append([]ScheduleAdjustment{}, l.Adjustments...), adjustment)
+	plan, err := planAdjustedSchedule(&adjusted, current)
+	if err != nil {
+		return nil, err
+	}
+	if len(plan.Changes) == 0 {
+		return nil, fmt.Errorf("%w: the %s from %s changes no installment of loan %d", apperrors.ErrInvalidArgument, adjustment.Kind, adjustment.StartsOn.Format("2006-01-02"), l.ID)
+	}
+	plan.Adjustment = adjustment
+	return plan, nil
+}
+
+// PlanAdjustmentRemoval works out what removing the active adjustment with
+// adjustmentID does to l and current. Only an adjustment that has not started
+// by today can be removed; one under way has already shaped what the
+// customer was asked to pay.
+func PlanAdjustmentRemoval(l *Loan, current []ScheduleEntry, adjustmentID int64, today time.Time) (*AdjustmentPlan, error) {
+	idx := slices.IndexFunc(l.Adjustments, func(a ScheduleAdjustment) bool { return a.ID == adjustmentID && a.active() })
+	if idx < 0 {
+		return nil, fmt.Errorf("%w: loan %d has no active adjustment %d", apperrors.ErrNotFound, l.ID, adjustmentID)
+	}
+	adjustment := l.Adjustments[idx]
+	if l.Status == StatusPaidOff {
+		return nil, fmt.Errorf("%w: loan %d is paid off", apperrors.ErrConflict, l.ID)
+	}
+	if !truncateToDate(adjustment.StartsOn).After(truncateToDate(today)) {
+		return nil, fmt.Errorf("%w: the %s from %s has started", apperrors.ErrConflict, adjustment.Kind, adjustment.StartsOn.Format("2006-01-02"))
+	}
+
+	adjusted := *l
+	adjusted.Adjustments = slices.Delete(slices.Clone(l.Adjustments), idx, idx+1)
+	plan, err := planAdjustedSchedule(&adjusted, current)
+	if err != nil {
+		return nil, err
+	}
+	plan.Adjustment = adjustment
+	return plan, nil
+}
+
+// planAdjustedSchedule compares current with the schedule adjusted's terms
+// give. Installments whose due date or amount differ are updated and keep
+// their status; one that carries a payment cannot move, which makes the
+// adjustment ErrConflict. Weeks missing from current are left to a schedule
+// rebuild.
+func planAdjustedSchedule(adjusted *Loan, current []ScheduleEntry) (*AdjustmentPlan, error) {
+	adjusted.TotalLoanAmount = adjusted.totalFromTerms()
+	expected, err := adjusted.GenerateSchedule()
+	if err != nil {
+		return nil, err
+	}
+	byWeek := make(map[int]*ScheduleEntry, len(expected))
+	for i := range expected {
+		byWeek[expected[i].WeekNumber] = &expected[i]
+	}
+
+	plan := &AdjustmentPlan{Changes: []ScheduleChange{}, TotalLoanAmount: adjusted.TotalLoanAmount}
+	for i := range current {
+		have := &current[i]
+		want, ok := byWeek[have.WeekNumber]
+		if !ok || (truncateToDate(have.DueDate).Equal(truncateToDate(want.DueDate)) && math.Abs(have.DueAmount-want.DueAmount) <= centTolerance) {
+			continue
+		}
+		if have.hasPayment() {
+			return nil, fmt.Errorf("%w: week %d of loan %d, due on %s, is already paid and cannot be adjusted",
+				apperrors.ErrConflict, have.WeekNumber, adjusted.ID, have.DueDate.Format("2006-01-02"))
+		}
+		after := *have
+		after.DueDate = want.DueDate
+		after.DueAmount = want.DueAmount
+		plan.Changes = append(plan.Changes, ScheduleChange{Kind: ScheduleEntryUpdated, WeekNumber: have.WeekNumber, Before: have, After: &after})
+	}
+	return plan, nil
+}
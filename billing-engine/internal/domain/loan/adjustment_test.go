@@ -0,0 +1,214 @@
+package loan
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanScheduleAdjustment(t *testing.T) {
+	start := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	today := time.Date(2025, 1, 10, 9, 0, 0, 0, time.UTC)
+	date := func(day string) time.Time {
+		d, err := time.Parse(time.DateOnly, day)
+		require.NoError(t, err)
+		return d
+	}
+
+	// newStored returns a three week loan of 110 a week, due on 13, 20 and 27
+	// January, and the schedule it was booked with.
+	newStored := func(t *testing.T) (*Loan, []ScheduleEntry) {
+		l, err := NewLoan(300, 3, 0.1, start)
+		require.NoError(t, err)
+		l.ID = 7
+		schedule, err := l.GenerateSchedule()
+		require.NoError(t, err)
+		for i := range schedule {
+			schedule[i].ID = int64(i + 1)
+			schedule[i].LoanID = l.ID
+		}
+		return l, schedule
+	}
+	holiday := func(startsOn string, weeks int) ScheduleAdjustment {
+		return ScheduleAdjustment{Kind: AdjustmentPaymentHoliday, StartsOn: date(startsOn), Weeks: weeks, Reason: "medical leave"}
+	}
+	zeroInterest := func(startsOn string, weeks int) ScheduleAdjustment {
+		return ScheduleAdjustment{Kind: AdjustmentZeroInterest, StartsOn: date(startsOn), Weeks: weeks, Reason: "loyalty promotion"}
+	}
+
+	t.Run("a payment holiday moves the installments due from its start", func(t *testing.T) {
+		l, stored := newStored(t)
+
+		plan, err := PlanScheduleAdjustment(l, stored, holiday("2025-01-20", 2), today)
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), plan.Adjustment.LoanID)
+		assert.Equal(t, 330.0, plan.TotalLoanAmount, "a holiday adds no interest")
+		require.Len(t, plan.Changes, 2)
+		for i, change := range plan.Changes {
+			assert.Equal(t, ScheduleEntryUpdated, change.Kind)
+			assert.Equal(t, i+2, change.WeekNumber)
+			assert.Equal(t, change.Before.DueDate.AddDate(0, 0, 14), change.After.DueDate)
+			assert.Equal(t, 110.0, change.After.DueAmount)
+		}
+	})
+
+	t.Run("a zero-interest window waives the interest of the installments due in it", func(t *testing.T) {
+		l, stored := newStored(t)
+
+		plan, err := PlanScheduleAdjustment(l, stored, zeroInterest("2025-01-20", 1), today)
+
+		require.NoError(t, err)
+		assert.Equal(t, 320.0, plan.TotalLoanAmount)
+		require.Len(t, plan.Changes, 1)
+		assert.Equal(t, 2, plan.Changes[0].WeekNumber)
+		assert.Equal(t, 100.0, plan.Changes[0].After.DueAmount)
+		assert.Equal(t, date("2025-01-20"), plan.Changes[0].After.DueDate)
+	})
+
+	t.Run("a zero-interest window applies to installments moved into it", func(t *testing.T) {
+		l, stored := newStored(t)
+		l.Adjustments = []ScheduleAdjustment{{ID: 1, LoanID: 7, Kind: AdjustmentPaymentHoliday, StartsOn: date("2025-01-27"), Weeks: 1, Reason: "leave"}}
+		stored[2].DueDate = date("2025-02-03")
+
+		plan, err := PlanScheduleAdjustment(l, stored, zeroInterest("2025-02-03", 1), today)
+
+		require.NoError(t, err)
+		require.Len(t, plan.Changes, 1)
+		assert.Equal(t, 3, plan.Changes[0].WeekNumber)
+		assert.Equal(t, 100.0, plan.Changes[0].After.DueAmount)
+	})
+
+	t.Run("rejects an adjustment overlapping another of its kind", func(t *testing.T) {
+		l, stored := newStored(t)
+		l.Adjustments = []ScheduleAdjustment{{ID: 1, LoanID: 7, Kind: AdjustmentZeroInterest, StartsOn: date("2025-01-13"), Weeks: 2, Reason: "promotion"}}
+
+		_, err := PlanScheduleAdjustment(l, stored, zeroInterest("2025-01-20", 1), today)
+
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+	})
+
+	t.Run("ignores removed adjustments", func(t *testing.T) {
+		l, stored := newStored(t)
+		removedAt := today
+		l.Adjustments = []ScheduleAdjustment{{ID: 1, LoanID: 7, Kind: AdjustmentZeroInterest, StartsOn: date("2025-01-13"), Weeks: 2, Reason: "promotion", RemovedAt: &removedAt}}
+
+		_, err := PlanScheduleAdjustment(l, stored, zeroInterest("2025-01-20", 1), today)
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("refuses a payment holiday to a delinquent loan", func(t *testing.T) {
+		l, stored := newStored(t)
+		l.Status = StatusDelinquent
+
+		_, err := PlanScheduleAdjustment(l, stored, holiday("2025-01-20", 1), today)
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+
+		_, err = PlanScheduleAdjustment(l, stored, zeroInterest("2025-01-20", 1), today)
+		assert.NoError(t, err, "waiving interest does not hide arrears")
+	})
+
+	t.Run("rejects a paid-off loan", func(t *testing.T) {
+		l, stored := newStored(t)
+		l.Status = StatusPaidOff
+
+		_, err := PlanScheduleAdjustment(l, stored, holiday("2025-01-20", 1), today)
+
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+	})
+
+	t.Run("rejects moving a paid installment", func(t *testing.T) {
+		l, stored := newStored(t)
+		stored[1].PaidAmount = 50
+
+		_, err := PlanScheduleAdjustment(l, stored, holiday("2025-01-20", 1), today)
+
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+	})
+
+	t.Run("rejects an adjustment that changes no installment", func(t *testing.T) {
+		l, stored := newStored(t)
+
+		_, err := PlanScheduleAdjustment(l, stored, zeroInterest("2025-02-10", 4), today)
+
+		assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+	})
+
+	t.Run("validates the adjustment", func(t *testing.T) {
+		l, stored := newStored(t)
+		tests := map[string]ScheduleAdjustment{
+			"unknown kind":    {Kind: "DISCOUNT", StartsOn: date("2025-01-20"), Weeks: 1, Reason: "r"},
+			"no weeks":        {Kind: AdjustmentPaymentHoliday, StartsOn: date("2025-01-20"), Reason: "r"},
+			"too many weeks":  {Kind: AdjustmentPaymentHoliday, StartsOn: date("2025-01-20"), Weeks: 53, Reason: "r"},
+			"no start":        {Kind: AdjustmentPaymentHoliday, Weeks: 1, Reason: "r"},
+			"blank reason":    {Kind: AdjustmentPaymentHoliday, StartsOn: date("2025-01-20"), Weeks: 1, Reason: "  "},
+			"started already": {Kind: AdjustmentPaymentHoliday, StartsOn: date("2025-01-09"), Weeks: 1, Reason: "r"},
+		}
+		for name, adjustment := range tests {
+			t.Run(name, func(t *testing.T) {
+				_, err := PlanScheduleAdjustment(l, stored, adjustment, today)
+				assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+			})
+		}
+	})
+}
+
+func TestPlanAdjustmentRemoval(t *testing.T) {
+	start := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	today := time.Date(2025, 1, 10, 9, 0, 0, 0, time.UTC)
+	holidayStart := time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC)
+
+	// newStored returns a three week loan of 110 a week with a two week payment
+	// holiday from 20 January, and the schedule that holiday gave it.
+	newStored := func(t *testing.T) (*Loan, []ScheduleEntry) {
+		l, err := NewLoan(300, 3, 0.1, start)
+		require.NoError(t, err)
+		l.ID = 7
+		l.Adjustments = []ScheduleAdjustment{{ID: 3, LoanID: 7, Kind: AdjustmentPaymentHoliday, StartsOn: holidayStart, Weeks: 2, Reason: "leave"}}
+		schedule, err := l.GenerateSchedule()
+		require.NoError(t, err)
+		for i := range schedule {
+			schedule[i].ID = int64(i + 1)
+			schedule[i].LoanID = l.ID
+		}
+		return l, schedule
+	}
+
+	t.Run("restores the schedule without the adjustment", func(t *testing.T) {
+		l, stored := newStored(t)
+		require.Equal(t, time.Date(2025, 2, 3, 0, 0, 0, 0, time.UTC), stored[1].DueDate)
+
+		plan, err := PlanAdjustmentRemoval(l, stored, 3, today)
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), plan.Adjustment.ID)
+		assert.Equal(t, 330.0, plan.TotalLoanAmount)
+		require.Len(t, plan.Changes, 2)
+		assert.Equal(t, time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC), plan.Changes[0].After.DueDate)
+		assert.Equal(t, time.Date(2025, 1, 27, 0, 0, 0, 0, time.UTC), plan.Changes[1].After.DueDate)
+	})
+
+	t.Run("rejects an adjustment under way", func(t *testing.T) {
+		l, stored := newStored(t)
+
+		_, err := PlanAdjustmentRemoval(l, stored, 3, holidayStart)
+
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+	})
+
+	t.Run("reports an unknown or removed adjustment", func(t *testing.T) {
+		l, stored := newStored(t)
+
+		_, err := PlanAdjustmentRemoval(l, stored, 4, today)
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+
+		removedAt := today
+		l.Adjustments[0].RemovedAt = &removedAt
+		_, err = PlanAdjustmentRemoval(l, stored, 3, today)
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+}
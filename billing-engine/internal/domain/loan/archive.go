@@ -0,0 +1,172 @@
+package loan
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ArchivePolicy decides which completed loans leave the live tables. A loan
+// is archived once it has been paid off for RetentionDays, counted from its
+// last payment.
+type ArchivePolicy struct {
+	RetentionDays int
+	// BatchSize bounds how many loans one run archives, so that a backlog
+	// is worked off over several runs.
+	BatchSize int
+}
+
+// DefaultArchivePolicy keeps paid-off loans for two years and archives up to
+// 1000 of them per run.
+func DefaultArchivePolicy() ArchivePolicy {
+	return ArchivePolicy{RetentionDays: 730, BatchSize: 1000}
+}
+
+// NewArchivePolicy validates the configured values.
+func NewArchivePolicy(retentionDays, batchSize int) (ArchivePolicy, error) {
+	if retentionDays < 1 {
+		return ArchivePolicy{}, fmt.Errorf("%w: retention must be at least 1 day, got %d", apperrors.ErrInvalidArgument, retentionDays)
+	}
+	if batchSize < 1 {
+		return ArchivePolicy{}, fmt.Errorf("%w: archive batch size must be at least 1, got %d", apperrors.ErrInvalidArgument, batchSize)
+	}
+	return ArchivePolicy{RetentionDays: retentionDays, BatchSize: batchSize}, nil
+}
+
+// Cutoff is the latest completion time a loan may have to be archived at now.
+func (p ArchivePolicy) Cutoff(now time.Time) time.Time {
+	return now.AddDate(0, 0, -p.RetentionDays)
+}
+
+// ArchivedLoan is the catalogue entry of a loan moved out of the live
+// tables. CustomerID is the customer the loan was assigned to when it was
+// archived. CompletedAt is its last payment, or its last update for a loan
+// paid off without one.
+type ArchivedLoan struct {
+	LoanID      int64
+	PublicID    uuid.UUID
+	CustomerID  *int64
+	CompletedAt time.Time
+	ArchivedAt  time.Time
+}
+
+// ArchiveReport sums up an archival run.
+type ArchiveReport struct {
+	Cutoff   time.Time
+	Archived []ArchivedLoan
+	// Skipped counts the loans that stopped qualifying between being listed
+	// and being archived, for example because a hold was placed on them.
+	Skipped int
+}
+
+// ArchiveRepository moves loans between the live tables and the archive. A
+// loan is archived together with everything that belongs to it: schedule,
+// payments, direct-debit instructions, status snapshots, collection history,
+// holds, notes and attachment metadata. Attachment objects stay where they
+// are in object storage.
+type ArchiveRepository interface {
+	// ListArchivable returns up to limit paid-off loans completed before
+	// cutoff that have no open hold or collection assignment, the longest
+	// completed first.
+	ListArchivable(ctx context.Context, cutoff time.Time, limit int) ([]int64, error)
+
+	// ArchiveLoan checks the loan still qualifies and moves it in one
+	// transaction. It returns ErrNotFound for an unknown loan and
+	// ErrConflict for one that no longer qualifies.
+	ArchiveLoan(ctx context.Context, loanID int64, cutoff, archivedAt time.Time) (*ArchivedLoan, error)
+
+	// RestoreLoan moves an archived loan back into the live tables and
+	// reassigns it to its customer if the customer has no loan now, which
+	// relinked reports. It returns ErrNotFound for a loan that is not
+	// archived and ErrConflict when a restored row would clash with a live
+	// one, such as an external reference that was reused.
+	RestoreLoan(ctx context.Context, loanID int64, restoredAt time.Time) (restored *ArchivedLoan, relinked bool, err error)
+
+	// ListArchived returns the catalogue, the most recently archived first.
+	ListArchived(ctx context.Context, limit int) ([]ArchivedLoan, error)
+}
+
+type ArchiveService interface {
+	// ArchiveCompleted archives one batch of the loans the policy says are
+	// due. It stops at the first loan that fails and returns what it
+	// archived until then together with the error.
+	ArchiveCompleted(ctx context.Context) (*ArchiveReport, error)
+
+	Restore(ctx context.Context, loanID int64) (*ArchivedLoan, bool, error)
+	ListArchived(ctx context.Context, limit int) ([]ArchivedLoan, error)
+}
+
+var _ ArchiveService = (*archiveService)(nil)
+
+type archiveService struct {
+	repo   ArchiveRepository
+	policy ArchivePolicy
+	clock  clock.Clock
+	logger *slog.Logger
+}
+
+// NewArchiveService wires loan archival. The clock decides which loans are
+// past the retention period and nil means the wall clock.
+func NewArchiveService(repo ArchiveRepository, policy ArchivePolicy, clk clock.Clock, logger *slog.Logger) ArchiveService {
+	if repo == nil {
+		panic("archive repository cannot be nil")
+	}
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+		logger.Warn("Warning: No logger provided to NewArchiveService, using default stderr handler")
+	}
+	return &archiveService{
+		repo:   repo,
+		policy: policy,
+		clock:  clock.OrSystem(clk),
+		logger: logger.With(slog.String("component", "archiveService")),
+	}
+}
+
+func (s *archiveService) ArchiveCompleted(ctx context.Context) (*ArchiveReport, error) {
+	now := s.clock.Now()
+	report := &ArchiveReport{Cutoff: s.policy.Cutoff(now), Archived: []ArchivedLoan{}}
+	ids, err := s.repo.ListArchivable(ctx, report.Cutoff, s.policy.BatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list loans to archive: %w", err)
+	}
+
+	for _, id := range ids {
+		archived, err := s.repo.ArchiveLoan(ctx, id, report.Cutoff, now)
+		if errors.Is(err, apperrors.ErrConflict) || errors.Is(err, apperrors.ErrNotFound) {
+			s.logger.InfoContext(ctx, "Loan no longer qualifies for archival", slog.Int64("loanID", id), slog.Any("reason", err))
+			report.Skipped++
+			continue
+		}
+		if err != nil {
+			return report, fmt.Errorf("failed to archive loan %d: %w", id, err)
+		}
+		report.Archived = append(report.Archived, *archived)
+	}
+	s.logger.InfoContext(ctx, "Archived completed loans",
+		slog.Time("cutoff", report.Cutoff), slog.Int("archived", len(report.Archived)), slog.Int("skipped", report.Skipped))
+	return report, nil
+}
+
+func (s *archiveService) Restore(ctx context.Context, loanID int64) (*ArchivedLoan, bool, error) {
+	restored, relinked, err := s.repo.RestoreLoan(ctx, loanID, s.clock.Now())
+	if err != nil {
+		return nil, false, err
+	}
+	s.logger.InfoContext(ctx, "Restored archived loan", slog.Int64("loanID", loanID), slog.Bool("relinked", relinked))
+	return restored, relinked, nil
+}
+
+func (s *archiveService) ListArchived(ctx context.Context, limit int) ([]ArchivedLoan, error) {
+	if limit < 1 {
+		return nil, fmt.Errorf("%w: limit must be at least 1, got %d", apperrors.ErrInvalidArgument, limit)
+	}
+	return s.repo.ListArchived(ctx, limit)
+}
@@ -0,0 +1,110 @@
+package loan
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockArchiveRepository struct {
+	mock.Mock
+}
+
+func (m *MockArchiveRepository) ListArchivable(ctx context.Context, cutoff time.Time, limit int) ([]int64, error) {
+	args := m.Called(ctx, cutoff, limit)
+	ids, _ := args.Get(0).([]int64)
+	return ids, args.Error(1)
+}
+
+func (m *MockArchiveRepository) ArchiveLoan(ctx context.Context, loanID int64, cutoff, archivedAt time.Time) (*ArchivedLoan, error) {
+	args := m.Called(ctx, loanID, cutoff, archivedAt)
+	a, _ := args.Get(0).(*ArchivedLoan)
+	return a, args.Error(1)
+}
+
+func (m *MockArchiveRepository) RestoreLoan(ctx context.Context, loanID int64, restoredAt time.Time) (*ArchivedLoan, bool, error) {
+	args := m.Called(ctx, loanID, restoredAt)
+	a, _ := args.Get(0).(*ArchivedLoan)
+	return a, args.Bool(1), args.Error(2)
+}
+
+func (m *MockArchiveRepository) ListArchived(ctx context.Context, limit int) ([]ArchivedLoan, error) {
+	args := m.Called(ctx, limit)
+	loans, _ := args.Get(0).([]ArchivedLoan)
+	return loans, args.Error(1)
+}
+
+func TestNewArchivePolicy(t *testing.T) {
+	p, err := NewArchivePolicy(365, 50)
+	require.NoError(t, err)
+	assert.Equal(t, day(2024, 3, 10), p.Cutoff(day(2025, 3, 10)))
+
+	_, err = NewArchivePolicy(0, 50)
+	assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+	_, err = NewArchivePolicy(365, 0)
+	assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+}
+
+func TestArchiveCompleted(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 3, 10, 4, 0, 0, 0, time.UTC)
+	cutoff := time.Date(2024, 3, 10, 4, 0, 0, 0, time.UTC)
+	policy := ArchivePolicy{RetentionDays: 365, BatchSize: 3}
+
+	t.Run("archives the listed loans and skips those that stopped qualifying", func(t *testing.T) {
+		repo := new(MockArchiveRepository)
+		repo.On("ListArchivable", ctx, cutoff, 3).Return([]int64{4, 5, 6}, nil).Once()
+		repo.On("ArchiveLoan", ctx, int64(4), cutoff, now).Return(&ArchivedLoan{LoanID: 4}, nil).Once()
+		repo.On("ArchiveLoan", ctx, int64(5), cutoff, now).Return(nil, apperrors.ErrConflict).Once()
+		repo.On("ArchiveLoan", ctx, int64(6), cutoff, now).Return(&ArchivedLoan{LoanID: 6}, nil).Once()
+
+		report, err := NewArchiveService(repo, policy, clock.NewFake(now), logger).ArchiveCompleted(ctx)
+
+		require.NoError(t, err)
+		assert.Equal(t, cutoff, report.Cutoff)
+		require.Len(t, report.Archived, 2)
+		assert.Equal(t, int64(6), report.Archived[1].LoanID)
+		assert.Equal(t, 1, report.Skipped)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("stops at a database error and reports what it archived", func(t *testing.T) {
+		repo := new(MockArchiveRepository)
+		repo.On("ListArchivable", ctx, cutoff, 3).Return([]int64{4, 5, 6}, nil).Once()
+		repo.On("ArchiveLoan", ctx, int64(4), cutoff, now).Return(&ArchivedLoan{LoanID: 4}, nil).Once()
+		repo.On("ArchiveLoan", ctx, int64(5), cutoff, now).Return(nil, apperrors.ErrDatabase).Once()
+
+		report, err := NewArchiveService(repo, policy, clock.NewFake(now), logger).ArchiveCompleted(ctx)
+
+		assert.ErrorIs(t, err, apperrors.ErrDatabase)
+		require.NotNil(t, report)
+		assert.Len(t, report.Archived, 1)
+		repo.AssertNotCalled(t, "ArchiveLoan", ctx, int64(6), cutoff, now)
+	})
+}
+
+func TestArchiveServiceRestore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 3, 10, 4, 0, 0, 0, time.UTC)
+	repo := new(MockArchiveRepository)
+	repo.On("RestoreLoan", ctx, int64(4), now).Return(&ArchivedLoan{LoanID: 4}, true, nil).Once()
+	repo.On("RestoreLoan", ctx, int64(9), now).Return(nil, false, apperrors.ErrNotFound).Once()
+	service := NewArchiveService(repo, DefaultArchivePolicy(), clock.NewFake(now), logger)
+
+	restored, relinked, err := service.Restore(ctx, 4)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), restored.LoanID)
+	assert.True(t, relinked)
+
+	_, _, err = service.Restore(ctx, 9)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+
+	_, err = service.ListArchived(ctx, 0)
+	assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+}
@@ -0,0 +1,51 @@
+package loan
+
+import (
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/pkg/apperrors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// CreditPolicy caps the principal of a new loan by the risk grade the credit
+// bureau gave the customer. Once any limit is set, customers who were never
+// scored and grades without a limit are refused; the zero policy checks
+// nothing.
+type CreditPolicy struct {
+	Limits map[string]Money
+}
+
+// NewCreditPolicy validates the configured limits. Grades are matched
+// without regard to case, because configuration keys are lowercased when
+// they are read.
+func NewCreditPolicy(limits map[string]Money) (CreditPolicy, error) {
+	p := CreditPolicy{Limits: make(map[string]Money, len(limits))}
+	for grade, limit := range limits {
+		grade = strings.ToUpper(strings.TrimSpace(grade))
+		if math.IsNaN(limit) || math.IsInf(limit, 0) || limit <= 0 {
+			return CreditPolicy{}, fmt.Errorf("%w: credit limit of grade %s must be positive, got %v", apperrors.ErrInvalidArgument, grade, limit)
+		}
+		p.Limits[grade] = limit
+	}
+	return p, nil
+}
+
+// check refuses a loan of principal to cust when it exceeds the limit of
+// the customer's grade.
+func (p CreditPolicy) check(cust *customer.Customer, principal Money) error {
+	if len(p.Limits) == 0 {
+		return nil
+	}
+	if !cust.Scored() {
+		return fmt.Errorf("%w: customer %d has no risk score yet", apperrors.ErrValidation, cust.CustomerID)
+	}
+	limit, ok := p.Limits[*cust.RiskGrade]
+	if !ok {
+		return fmt.Errorf("%w: customer %d has risk grade %s, which is not eligible for credit", apperrors.ErrValidation, cust.CustomerID, *cust.RiskGrade)
+	}
+	if principal > limit {
+		return fmt.Errorf("%w: principal %.2f exceeds the credit limit of %.2f for risk grade %s", apperrors.ErrValidation, principal, limit, *cust.RiskGrade)
+	}
+	return nil
+}
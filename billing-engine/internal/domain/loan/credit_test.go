@@ -0,0 +1,80 @@
+package loan
+
+import (
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func scoredCustomer(grade string) *customer.Customer {
+	score := 640
+	scoredAt := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	return &customer.Customer{CustomerID: 1, Active: true, RiskScore: &score, RiskGrade: &grade, RiskScoredAt: &scoredAt}
+}
+
+func TestNewCreditPolicy(t *testing.T) {
+	p, err := NewCreditPolicy(map[string]Money{"a": 10000000, " B+ ": 5000000})
+
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Money{"A": 10000000, "B+": 5000000}, p.Limits)
+
+	for name, limit := range map[string]Money{"zero": 0, "negative": -1, "NaN": Money(math.NaN())} {
+		t.Run("rejects a "+name+" limit", func(t *testing.T) {
+			_, err := NewCreditPolicy(map[string]Money{"A": limit})
+
+			assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+		})
+	}
+}
+
+func TestCreditPolicyCheck(t *testing.T) {
+	p := CreditPolicy{Limits: map[string]Money{"A": 10000000, "B": 5000000}}
+
+	assert.NoError(t, p.check(scoredCustomer("B"), 5000000))
+	assert.ErrorIs(t, p.check(scoredCustomer("B"), 5000000.01), apperrors.ErrValidation)
+	assert.ErrorIs(t, p.check(scoredCustomer("E"), 100), apperrors.ErrValidation)
+	assert.ErrorIs(t, p.check(&customer.Customer{CustomerID: 1, Active: true}, 100), apperrors.ErrValidation)
+	assert.NoError(t, CreditPolicy{}.check(&customer.Customer{CustomerID: 1, Active: true}, 100000000))
+}
+
+func TestCreateLoanCreditPolicy(t *testing.T) {
+	ctx := context.Background()
+	credit := CreditPolicy{Limits: map[string]Money{"A": 10000000}}
+	newService := func(cust *customer.Customer) (LoanService, *MockRepository, *MockCustomerService) {
+		mockRepo := new(MockRepository)
+		mockCustomerService := new(MockCustomerService)
+		mockCustomerService.On("GetCustomer", ctx, int64(1)).Return(cust, nil)
+		mockCustomerService.On("RequestRiskScore", ctx, cust, customer.RiskScoreLoanApplication, Money(20000000)).Return().Once()
+		return NewLoanService(mockRepo, mockCustomerService, DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, credit, ProductCatalog{}, nil, logger), mockRepo, mockCustomerService
+	}
+
+	t.Run("refuses a principal above the grade's limit", func(t *testing.T) {
+		service, mockRepo, mockCustomerService := newService(scoredCustomer("A"))
+
+		_, err := service.CreateLoan(ctx, 1, 20000000, 50, 10, time.Now(), "", uuid.Nil, "")
+
+		assert.ErrorIs(t, err, apperrors.ErrValidation)
+		assert.Contains(t, err.Error(), "exceeds the credit limit")
+		mockRepo.AssertNotCalled(t, "CreateLoan", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockCustomerService.AssertExpectations(t)
+	})
+
+	t.Run("refuses a customer not scored yet but requests a score", func(t *testing.T) {
+		service, mockRepo, mockCustomerService := newService(&customer.Customer{CustomerID: 1, Active: true})
+
+		_, err := service.CreateLoan(ctx, 1, 20000000, 50, 10, time.Now(), "", uuid.Nil, "")
+
+		assert.ErrorIs(t, err, apperrors.ErrValidation)
+		assert.Contains(t, err.Error(), "no risk score")
+		mockRepo.AssertNotCalled(t, "CreateLoan", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockCustomerService.AssertExpectations(t)
+	})
+}
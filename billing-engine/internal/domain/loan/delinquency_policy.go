@@ -0,0 +1,179 @@
+package loan
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// DelinquencyPolicy decides when a customer is flagged as delinquent and
+// when the flag is lifted again. The two thresholds differ so that a customer
+// paying irregularly around a single threshold is not flagged and cleared on
+// alternate days.
+type DelinquencyPolicy struct {
+	// MissedPayments is how many installments must be past due and unpaid
+	// before a customer is flagged.
+	MissedPayments int
+	// CurePayments is how many consecutive installments must have been met
+	// on time to lift the flag while the customer is still behind. An
+	// installment is met on time when a payment arrived in its week, on or
+	// before its due date. Catching up on every past-due installment lifts
+	// the flag straight away.
+	CurePayments int
+	// GraceDays is how many days past its due date an unpaid installment
+	// is still not missed.
+	GraceDays int
+	// LateFee is charged once for each installment still unpaid after the
+	// grace period. Zero charges none.
+	LateFee Money
+}
+
+// DefaultDelinquencyPolicy flags a customer after two missed payments and
+// clears them after two on-time payments. It has no grace period and no
+// late fee.
+func DefaultDelinquencyPolicy() DelinquencyPolicy {
+	return DelinquencyPolicy{MissedPayments: 2, CurePayments: 2}
+}
+
+// DelinquencyPolicySource gives the policy in force when a loan is judged.
+// A DelinquencyPolicy is its own source; tenant settings can supply one that
+// changes at run time.
+type DelinquencyPolicySource interface {
+	Current(ctx context.Context) (DelinquencyPolicy, error)
+}
+
+// Current returns p itself.
+func (p DelinquencyPolicy) Current(context.Context) (DelinquencyPolicy, error) {
+	return p, nil
+}
+
+// NewDelinquencyPolicy validates the configured values.
+func NewDelinquencyPolicy(missedPayments, curePayments, graceDays int, lateFee Money) (DelinquencyPolicy, error) {
+	if missedPayments < 1 {
+		return DelinquencyPolicy{}, fmt.Errorf("%w: missed payments must be at least 1, got %d", apperrors.ErrInvalidArgument, missedPayments)
+	}
+	if curePayments < 1 {
+		return DelinquencyPolicy{}, fmt.Errorf("%w: cure payments must be at least 1, got %d", apperrors.ErrInvalidArgument, curePayments)
+	}
+	if graceDays < 0 {
+		return DelinquencyPolicy{}, fmt.Errorf("%w: grace days must not be negative, got %d", apperrors.ErrInvalidArgument, graceDays)
+	}
+	if math.IsNaN(lateFee) || math.IsInf(lateFee, 0) || lateFee < 0 {
+		return DelinquencyPolicy{}, fmt.Errorf("%w: late fee must not be negative, got %v", apperrors.ErrInvalidArgument, lateFee)
+	}
+	return DelinquencyPolicy{MissedPayments: missedPayments, CurePayments: curePayments, GraceDays: graceDays, LateFee: roundTo(lateFee, 2)}, nil
+}
+
+// Evaluate reports whether the customer of a loan with schedule is delinquent
+// as of asOf, given whether they are flagged now. Installments due on asOf
+// itself, or less than GraceDays before it, are not yet missed.
+//
+// An unflagged customer is flagged once MissedPayments installments are past
+// due, unless their last CurePayments installments were met on time: a
+// customer who is behind but paying regularly stays unflagged. A flagged
+// customer stays flagged until they catch up or meet CurePayments
+// installments in a row.
+func (p DelinquencyPolicy) Evaluate(schedule []ScheduleEntry, asOf time.Time, flagged bool) bool {
+	today := truncateToDate(asOf)
+	due := make([]ScheduleEntry, 0, len(schedule))
+	missed := 0
+	for _, entry := range schedule {
+		if !p.pastGrace(entry, today) {
+			continue
+		}
+		due = append(due, entry)
+		if entry.Status != PaymentStatusPaid {
+			missed++
+		}
+	}
+
+	threshold := p.MissedPayments
+	if flagged {
+		threshold = 1
+	}
+	if missed < threshold {
+		return false
+	}
+	return !p.paidOnTime(schedule, due)
+}
+
+// LateInstallments returns the installments of schedule still unpaid as of
+// asOf once their grace period is over, oldest first. These are the ones a
+// late fee is charged for.
+func (p DelinquencyPolicy) LateInstallments(schedule []ScheduleEntry, asOf time.Time) []ScheduleEntry {
+	today := truncateToDate(asOf)
+	var late []ScheduleEntry
+	for _, entry := range schedule {
+		if entry.Status != PaymentStatusPaid && p.pastGrace(entry, today) {
+			late = append(late, entry)
+		}
+	}
+	sort.Slice(late, func(i, j int) bool { return late[i].DueDate.Before(late[j].DueDate) })
+	return late
+}
+
+// pastGrace reports whether the grace period of entry was over before today.
+func (p DelinquencyPolicy) pastGrace(entry ScheduleEntry, today time.Time) bool {
+	return truncateToDate(entry.DueDate).AddDate(0, 0, p.GraceDays).Before(today)
+}
+
+// paidOnTime reports whether each of the last CurePayments installments in
+// due had a payment in its week. Payments settle the oldest installment
+// first, so the payment made in a week may be recorded on an earlier one.
+func (p DelinquencyPolicy) paidOnTime(schedule, due []ScheduleEntry) bool {
+	if len(due) < p.CurePayments {
+		return false
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].DueDate.Before(due[j].DueDate) })
+
+	var payments []time.Time
+	for _, entry := range schedule {
+		if entry.Status == PaymentStatusPaid && entry.PaymentDate != nil {
+			payments = append(payments, truncateToDate(entry.PaymentDate.UTC()))
+		}
+	}
+
+	for i := len(due) - p.CurePayments; i < len(due); i++ {
+		end := truncateToDate(due[i].DueDate)
+		start := end.AddDate(0, 0, -7)
+		if i > 0 {
+			start = truncateToDate(due[i-1].DueDate)
+		}
+		met := false
+		for _, paidOn := range payments {
+			if paidOn.After(start) && !paidOn.After(end) {
+				met = true
+				break
+			}
+		}
+		if !met {
+			return false
+		}
+	}
+	return true
+}
+
+// DaysPastDue counts the days from the due date of the oldest unpaid
+// installment to asOf, or returns 0 when nothing is past due. An installment
+// due on asOf is not overdue yet, as for Evaluate and the dpd of the daily
+// snapshots.
+func DaysPastDue(schedule []ScheduleEntry, asOf time.Time) int {
+	today := truncateToDate(asOf)
+	var oldest time.Time
+	for _, entry := range schedule {
+		due := truncateToDate(entry.DueDate)
+		if entry.Status == PaymentStatusPaid || !due.Before(today) {
+			continue
+		}
+		if oldest.IsZero() || due.Before(oldest) {
+			oldest = due
+		}
+	}
+	if oldest.IsZero() {
+		return 0
+	}
+	return int(today.Sub(oldest).Hours() / 24)
+}
@@ -0,0 +1,112 @@
+package loan
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewDelinquencyPolicy(t *testing.T) {
+	policy, err := NewDelinquencyPolicy(3, 1, 5, 12.345)
+	require.NoError(t, err)
+	assert.Equal(t, DelinquencyPolicy{MissedPayments: 3, CurePayments: 1, GraceDays: 5, LateFee: 12.35}, policy)
+
+	_, err = NewDelinquencyPolicy(0, 2, 0, 0)
+	assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+	_, err = NewDelinquencyPolicy(2, 0, 0, 0)
+	assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+	_, err = NewDelinquencyPolicy(2, 2, -1, 0)
+	assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+	_, err = NewDelinquencyPolicy(2, 2, 0, -5)
+	assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+}
+
+func TestDelinquencyPolicyEvaluate(t *testing.T) {
+	asOf := time.Date(2025, 2, 3, 9, 0, 0, 0, time.UTC)
+	day := func(d int) time.Time { return time.Date(2025, 1, d, 0, 0, 0, 0, time.UTC) }
+	// Installments are due on 6, 13, 20 and 27 January, and on asOf.
+	schedule := func(paidOn ...time.Time) []ScheduleEntry {
+		entries := make([]ScheduleEntry, 5)
+		for i := range entries {
+			entries[i] = ScheduleEntry{WeekNumber: i + 1, DueDate: day(6).AddDate(0, 0, 7*i), Status: PaymentStatusPending}
+		}
+		for i, paid := range paidOn {
+			entries[i].Status = PaymentStatusPaid
+			entries[i].PaymentDate = &paid
+		}
+		return entries
+	}
+
+	tests := []struct {
+		name     string
+		policy   DelinquencyPolicy
+		schedule []ScheduleEntry
+		flagged  bool
+		want     bool
+	}{
+		{name: "up to date", policy: DefaultDelinquencyPolicy(), schedule: schedule(day(6), day(13), day(20), day(27)), want: false},
+		{name: "one missed", policy: DefaultDelinquencyPolicy(), schedule: schedule(day(6), day(13), day(20)), want: false},
+		{name: "two missed", policy: DefaultDelinquencyPolicy(), schedule: schedule(day(6), day(13)), want: true},
+		{name: "higher threshold", policy: DelinquencyPolicy{MissedPayments: 3, CurePayments: 2}, schedule: schedule(day(6), day(13)), want: false},
+		{name: "flagged and one behind", policy: DefaultDelinquencyPolicy(), schedule: schedule(day(6), day(13), day(28)), flagged: true, want: true},
+		{name: "flagged and caught up", policy: DefaultDelinquencyPolicy(), schedule: schedule(day(6), day(13), day(28), day(30)), flagged: true, want: false},
+		{
+			// The payments made in the last two weeks settled the two oldest
+			// installments.
+			name: "flagged and cured by two on-time payments", policy: DefaultDelinquencyPolicy(),
+			schedule: schedule(day(20), day(27)), flagged: true, want: false,
+		},
+		{name: "two on-time payments keep a behind customer unflagged", policy: DefaultDelinquencyPolicy(), schedule: schedule(day(20), day(27)), want: false},
+		{name: "late payment breaks the streak", policy: DefaultDelinquencyPolicy(), schedule: schedule(day(21), day(27)), flagged: true, want: true},
+		{name: "shorter streak cures", policy: DelinquencyPolicy{MissedPayments: 2, CurePayments: 1}, schedule: schedule(day(27)), flagged: true, want: false},
+		// The installment of 27 January is still in its grace period.
+		{name: "grace period", policy: DelinquencyPolicy{MissedPayments: 2, CurePayments: 2, GraceDays: 7}, schedule: schedule(day(6), day(13)), want: false},
+		{name: "grace period over", policy: DelinquencyPolicy{MissedPayments: 2, CurePayments: 2, GraceDays: 6}, schedule: schedule(day(6), day(13)), want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.policy.Evaluate(tt.schedule, asOf, tt.flagged))
+		})
+	}
+}
+
+func TestDelinquencyPolicyLateInstallments(t *testing.T) {
+	asOf := time.Date(2025, 2, 3, 9, 0, 0, 0, time.UTC)
+	paidOn := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	schedule := []ScheduleEntry{
+		{WeekNumber: 3, DueDate: time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC), Status: PaymentStatusPending},
+		{WeekNumber: 1, DueDate: time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC), Status: PaymentStatusPaid, PaymentDate: &paidOn},
+		{WeekNumber: 2, DueDate: time.Date(2025, 1, 13, 0, 0, 0, 0, time.UTC), Status: PaymentStatusPending},
+		{WeekNumber: 4, DueDate: time.Date(2025, 1, 27, 0, 0, 0, 0, time.UTC), Status: PaymentStatusPending},
+	}
+	weeks := func(entries []ScheduleEntry) []int {
+		var weeks []int
+		for _, e := range entries {
+			weeks = append(weeks, e.WeekNumber)
+		}
+		return weeks
+	}
+
+	assert.Equal(t, []int{2, 3, 4}, weeks(DefaultDelinquencyPolicy().LateInstallments(schedule, asOf)), "unpaid and past due, oldest first")
+	assert.Equal(t, []int{2, 3}, weeks(DelinquencyPolicy{GraceDays: 7}.LateInstallments(schedule, asOf)), "the last one is still in its grace period")
+	assert.Empty(t, DelinquencyPolicy{GraceDays: 30}.LateInstallments(schedule, asOf))
+}
+
+func TestDaysPastDue(t *testing.T) {
+	asOf := time.Date(2025, 2, 3, 22, 0, 0, 0, time.UTC)
+	paidOn := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	schedule := []ScheduleEntry{
+		{WeekNumber: 1, DueDate: time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC), Status: PaymentStatusPaid, PaymentDate: &paidOn},
+		{WeekNumber: 2, DueDate: time.Date(2025, 1, 13, 0, 0, 0, 0, time.UTC), Status: PaymentStatusPending},
+		{WeekNumber: 3, DueDate: time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC), Status: PaymentStatusPending},
+		{WeekNumber: 4, DueDate: time.Date(2025, 2, 3, 0, 0, 0, 0, time.UTC), Status: PaymentStatusPending},
+	}
+
+	assert.Equal(t, 21, DaysPastDue(schedule, asOf), "counts from the oldest unpaid installment")
+	assert.Equal(t, 0, DaysPastDue(schedule[3:], asOf), "an installment due today is not overdue")
+	assert.Equal(t, 0, DaysPastDue(schedule[:1], asOf))
+	assert.Equal(t, 0, DaysPastDue(nil, asOf))
+}
@@ -0,0 +1,179 @@
+package loan
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+const maxFeeReasonLength = 500
+
+// FeeType is an entry of the fee catalog.
+type FeeType string
+
+const (
+	FeeProcessing FeeType = "PROCESSING"
+	// FeeBounce is charged when a collection is returned unpaid, such as a
+	// failed direct debit.
+	FeeBounce FeeType = "BOUNCE"
+	FeeLegal  FeeType = "LEGAL"
+	// FeeLate is charged by the delinquency job for an installment still
+	// unpaid once its grace period is over.
+	FeeLate FeeType = "LATE"
+)
+
+// FeeTypeInfo describes a fee type to the staff posting it.
+type FeeTypeInfo struct {
+	Type        FeeType
+	Name        string
+	Description string
+}
+
+var feeCatalog = []FeeTypeInfo{
+	{FeeProcessing, "Processing fee", "Charged for handling a request on the loan, such as a restructure."},
+	{FeeBounce, "Bounce fee", "Charged when a payment or collection is returned unpaid."},
+	{FeeLegal, "Legal fee", "Recovers legal costs incurred collecting the loan."},
+	{FeeLate, "Late fee", "Charged once for an installment still unpaid after the grace period."},
+}
+
+// FeeCatalog returns the fee types that can be posted, in display order.
+func FeeCatalog() []FeeTypeInfo {
+	return append([]FeeTypeInfo(nil), feeCatalog...)
+}
+
+func (t FeeType) info() (FeeTypeInfo, bool) {
+	for _, info := range feeCatalog {
+		if info.Type == t {
+			return info, true
+		}
+	}
+	return FeeTypeInfo{}, false
+}
+
+// WaiverStatus is where a fee waiver is in its approval.
+type WaiverStatus string
+
+const (
+	WaiverPending  WaiverStatus = "PENDING"
+	WaiverApproved WaiverStatus = "APPROVED"
+	WaiverRejected WaiverStatus = "REJECTED"
+)
+
+// Fee is a charge posted to a loan outside its schedule. It is owed on top
+// of the installments until it is paid or a waiver of it is approved.
+type Fee struct {
+	ID       int64
+	LoanID   int64
+	Type     FeeType
+	Amount   Money
+	Reason   string
+	PostedBy *string
+	PostedAt time.Time
+	// Tax is the tax charged on the fee, nil when its type is not taxed.
+	Tax *TaxLine
+	// PaidAt is when a payment settled the fee with its tax, nil while it is
+	// unpaid.
+	PaidAt *time.Time
+	// Waivers are the waivers requested for the fee, oldest first. At most
+	// one is pending or approved.
+	Waivers []FeeWaiver
+}
+
+// FeeWaiver is a request to write a fee off. One member of staff requests
+// it and another approves or rejects it; rejected requests are kept.
+type FeeWaiver struct {
+	ID          int64
+	FeeID       int64
+	LoanID      int64
+	Reason      string
+	Status      WaiverStatus
+	RequestedBy *string
+	RequestedAt time.Time
+	DecidedBy   *string
+	DecidedAt   *time.Time
+}
+
+// Waived reports whether a waiver of the fee was approved.
+func (f *Fee) Waived() bool {
+	for _, w := range f.Waivers {
+		if w.Status == WaiverApproved {
+			return true
+		}
+	}
+	return false
+}
+
+// Owed is the fee with its tax, which a payment of it must settle.
+func (f *Fee) Owed() Money {
+	if f.Tax == nil {
+		return f.Amount
+	}
+	return f.Amount + f.Tax.Amount
+}
+
+// PendingWaiver returns the waiver awaiting a decision, or nil.
+func (f *Fee) PendingWaiver() *FeeWaiver {
+	for i := range f.Waivers {
+		if f.Waivers[i].Status == WaiverPending {
+			return &f.Waivers[i]
+		}
+	}
+	return nil
+}
+
+// BalanceItem is the fee as the outstanding breakdown lists it.
+func (f *Fee) BalanceItem() BalanceItem {
+	name := string(f.Type)
+	if info, ok := f.Type.info(); ok {
+		name = info.Name
+	}
+	return BalanceItem{Kind: BalanceFee, Amount: f.Amount, Description: name + ": " + f.Reason}
+}
+
+// validateFee checks a fee about to be posted and returns its amount rounded
+// to cents and its trimmed reason.
+func validateFee(feeType FeeType, amount Money, reason string) (Money, string, error) {
+	if _, ok := feeType.info(); !ok {
+		return 0, "", fmt.Errorf("%w: unknown fee type %q", apperrors.ErrInvalidArgument, feeType)
+	}
+	if math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return 0, "", fmt.Errorf("%w: fee amount must be positive", apperrors.ErrInvalidArgument)
+	}
+	if amount = roundTo(amount, 2); amount <= 0 {
+		return 0, "", fmt.Errorf("%w: fee amount must be positive", apperrors.ErrInvalidArgument)
+	}
+	reason, err := validateFeeReason(reason, "a fee")
+	if err != nil {
+		return 0, "", err
+	}
+	return amount, reason, nil
+}
+
+func validateFeeReason(reason, what string) (string, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return "", fmt.Errorf("%w: %s needs a reason", apperrors.ErrInvalidArgument, what)
+	}
+	if len(reason) > maxFeeReasonLength {
+		return "", fmt.Errorf("%w: reason must be at most %d characters", apperrors.ErrInvalidArgument, maxFeeReasonLength)
+	}
+	return reason, nil
+}
+
+// checkWaiverDecision enforces that a waiver is decided by someone other
+// than whoever requested it. A decision or request without a name cannot be
+// told apart from its counterpart, so it is refused rather than let through.
+func checkWaiverDecision(w *FeeWaiver, decidedBy string) error {
+	if decidedBy == "" {
+		return fmt.Errorf("%w: deciding waiver %d needs a named approver", apperrors.ErrForbidden, w.ID)
+	}
+	if w.RequestedBy == nil {
+		return fmt.Errorf("%w: waiver %d has no recorded requester and cannot be decided", apperrors.ErrForbidden, w.ID)
+	}
+	if *w.RequestedBy == decidedBy {
+		return fmt.Errorf("%w: %s requested waiver %d and cannot decide it", apperrors.ErrForbidden, decidedBy, w.ID)
+	}
+	return nil
+}
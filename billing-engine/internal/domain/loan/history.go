@@ -0,0 +1,21 @@
+package loan
+
+import (
+	"context"
+	"time"
+)
+
+// HistoryRepository reads the row versions that database triggers record for
+// every change to loans and loan_schedule. It answers what a record looked
+// like at a given instant, which is what disputes about past balances and
+// statuses need.
+type HistoryRepository interface {
+	// GetLoanAt returns the loan as it was at the instant at. It fails with
+	// apperrors.ErrNotFound when the loan did not exist at that time.
+	GetLoanAt(ctx context.Context, loanID int64, at time.Time) (*Loan, error)
+
+	// GetScheduleAt returns the installments of the loan as they were at the
+	// instant at, ordered by week number. The slice is empty when the loan
+	// had no schedule then.
+	GetScheduleAt(ctx context.Context, loanID int64, at time.Time) ([]ScheduleEntry, error)
+}
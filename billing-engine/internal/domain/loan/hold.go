@@ -0,0 +1,36 @@
+package loan
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const maxHoldReasonLength = 500
+
+// Hold is an administrative hold on a loan, placed for maintenance such as a
+// dispute or a data correction. While it is open the loan takes no payments
+// or prepayments, its schedule is not repriced, adjusted or rebuilt, and it
+// is left out of direct-debit collection. Released holds are kept as
+// history.
+type Hold struct {
+	ID         int64
+	LoanID     int64
+	Reason     string
+	PlacedBy   *string
+	PlacedAt   time.Time
+	ReleasedBy *string
+	ReleasedAt *time.Time
+}
+
+func validateHoldReason(reason string) (string, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return "", fmt.Errorf("%w: a hold needs a reason", apperrors.ErrInvalidArgument)
+	}
+	if len(reason) > maxHoldReasonLength {
+		return "", fmt.Errorf("%w: hold reason must be at most %d characters", apperrors.ErrInvalidArgument, maxHoldReasonLength)
+	}
+	return reason, nil
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"math"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 const (
@@ -31,6 +33,7 @@ const (
 
 type Loan struct {
 	ID                  int64
+	PublicID            uuid.UUID
 	PrincipalAmount     float64
 	InterestRate        float64
 	TermWeeks           int
@@ -38,9 +41,41 @@ type Loan struct {
 	TotalLoanAmount     float64
 	StartDate           time.Time
 	Status              LoanStatus
+	ExternalRef         *string
 	CreatedAt           time.Time
 	UpdatedAt           time.Time
 	Schedule            []ScheduleEntry
+	// DaysPastDue is how many days the oldest unpaid installment is overdue,
+	// as last computed by the nightly delinquency job.
+	DaysPastDue int
+	// NextDueDate and NextDueAmount are the oldest unpaid installment's due
+	// date and what is left to pay on it; NextDueDate is nil once every
+	// installment is paid. InstallmentsPaid and InstallmentsRemaining count
+	// the schedule's paid and unpaid weeks. The database keeps all four up to
+	// date with every write to the schedule.
+	NextDueDate           *time.Time
+	NextDueAmount         Money
+	InstallmentsPaid      int
+	InstallmentsRemaining int
+	// Hold is the open administrative hold. Only GetLoan fills it in, and
+	// only for staff.
+	Hold *Hold
+	// RateHistory lists the loan's rate changes, oldest first. InterestRate
+	// is the latest one's rate. Only GetLoan and the repricing calls fill it
+	// in.
+	RateHistory []RateChange
+	// Adjustments lists the loan's payment holidays and zero-interest
+	// windows, removed ones included, in the order they were applied. GetLoan
+	// and the calls that reshape the schedule fill it in.
+	Adjustments []ScheduleAdjustment
+	// Prepayments lists the lump sums paid against the principal, oldest
+	// first. TotalLoanAmount includes them, so the installments add up to
+	// TotalLoanAmount less the prepayments. GetLoan and the calls that
+	// reshape the schedule fill it in.
+	Prepayments []Prepayment
+	// Structure is the shape of the schedule the loan was booked with.
+	// WeeklyPaymentAmount is its regular installment.
+	Structure ScheduleStructure
 }
 
 type ScheduleEntry struct {
@@ -56,6 +91,44 @@ type ScheduleEntry struct {
 	UpdatedAt   time.Time
 }
 
+// LastModified is the latest UpdatedAt of the loan and its loaded schedule,
+// matching Repository.GetLastModified.
+func (l *Loan) LastModified() time.Time {
+	latest := l.UpdatedAt
+	for _, entry := range l.Schedule {
+		if entry.UpdatedAt.After(latest) {
+			latest = entry.UpdatedAt
+		}
+	}
+	return latest
+}
+
+// SummarizeInstallments sets the loan's next installment and installment
+// counts from schedule, the way the database derives them, for a loan just
+// written whose columns were read back before its schedule went in.
+func (l *Loan) SummarizeInstallments(schedule []ScheduleEntry) {
+	l.NextDueDate, l.NextDueAmount = nil, 0
+	l.InstallmentsPaid, l.InstallmentsRemaining = 0, 0
+	var next *ScheduleEntry
+	for i := range schedule {
+		entry := &schedule[i]
+		if entry.Status == PaymentStatusPaid {
+			l.InstallmentsPaid++
+			continue
+		}
+		l.InstallmentsRemaining++
+		if next == nil || entry.DueDate.Before(next.DueDate) ||
+			(entry.DueDate.Equal(next.DueDate) && entry.WeekNumber < next.WeekNumber) {
+			next = entry
+		}
+	}
+	if next != nil {
+		due := next.DueDate
+		l.NextDueDate = &due
+		l.NextDueAmount = roundTo(next.DueAmount-next.PaidAmount, 2)
+	}
+}
+
 func NewLoan(principal float64, termWeeks int, annualInterestRate float64, startDate time.Time) (*Loan, error) {
 	if principal < 0 {
 		return nil, fmt.Errorf("%w: principal amount must be positive", apperrors.ErrInvalidArgument)
@@ -67,11 +140,11 @@ func NewLoan(principal float64, termWeeks int, annualInterestRate float64, start
 		return nil, fmt.Errorf("%w: term weeks must be positive", apperrors.ErrInvalidArgument)
 	}
 	if startDate.IsZero() {
-
-		startDate = time.Now().Truncate(24 * time.Hour)
+		return nil, fmt.Errorf("%w: start date is required", apperrors.ErrInvalidArgument)
 	}
 
 	loan := &Loan{
+		PublicID:        uuid.New(),
 		PrincipalAmount: principal,
 		TermWeeks:       termWeeks,
 		InterestRate:    annualInterestRate,
@@ -80,7 +153,9 @@ func NewLoan(principal float64, termWeeks int, annualInterestRate float64, start
 	}
 
 	totalInterest := loan.PrincipalAmount * loan.InterestRate
-	loan.TotalLoanAmount = loan.PrincipalAmount + totalInterest
+	// Rounded to cents as the total_loan_amount column stores it, so the
+	// schedule adds up to the amount that is persisted.
+	loan.TotalLoanAmount = roundTo(loan.PrincipalAmount+totalInterest, 2)
 
 	loan.WeeklyPaymentAmount = roundTo(loan.TotalLoanAmount/float64(loan.TermWeeks), 2)
 
@@ -98,12 +173,12 @@ func (l *Loan) GenerateSchedule() ([]ScheduleEntry, error) {
 
 	for week := 1; week <= l.TermWeeks; week++ {
 
-		currentDueDate = l.StartDate.AddDate(0, 0, week*7)
+		currentDueDate = l.dueDateOf(week)
 
-		paymentAmount := l.WeeklyPaymentAmount
+		paymentAmount := l.installmentDue(week, currentDueDate)
 		if week == l.TermWeeks {
 
-			paymentAmount = roundTo(l.TotalLoanAmount-accumulatedPayment, 2)
+			paymentAmount = roundTo(l.TotalLoanAmount-l.prepaid()-accumulatedPayment, 2)
 			if paymentAmount < 0 {
 				paymentAmount = 0
 			}
@@ -120,11 +195,8 @@ func (l *Loan) GenerateSchedule() ([]ScheduleEntry, error) {
 		accumulatedPayment += paymentAmount
 	}
 
-	finalAccumulated := roundTo(accumulatedPayment, 2)
-	expectedTotal := roundTo(l.TotalLoanAmount, 2)
-	if math.Abs(finalAccumulated-expectedTotal) > 0.01 {
-		return nil, fmt.Errorf("%w: schedule generation failed sanity check - total payment %.2f != expected total %.2f",
-			apperrors.ErrInternalServer, finalAccumulated, expectedTotal)
+	if err := CheckScheduleInvariants(l, schedule); err != nil {
+		return nil, fmt.Errorf("%w: schedule generation failed sanity check - %w", apperrors.ErrInternalServer, err)
 	}
 
 	return schedule, nil
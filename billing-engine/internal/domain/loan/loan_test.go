@@ -1,6 +1,8 @@
 package loan
 
 import (
+	"billing-engine/internal/pkg/apperrors"
+	"fmt"
 	"testing"
 	"time"
 
@@ -32,6 +34,11 @@ func TestNewLoan(t *testing.T) {
 		_, err := NewLoan(1_000_000, 0, 0.05, time.Now())
 		assert.Error(t, err)
 	})
+
+	t.Run("should return error without a start date", func(t *testing.T) {
+		_, err := NewLoan(1_000_000, 52, 0.05, time.Time{})
+		assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+	})
 }
 
 func TestGenerateSchedule(t *testing.T) {
@@ -81,3 +88,49 @@ func TestGenerateSchedule(t *testing.T) {
 		assert.InDelta(t, loan.TotalLoanAmount, accumulatedPayment, 0.01)
 	})
 }
+
+func TestLoanLastModified(t *testing.T) {
+	base := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	l := &Loan{UpdatedAt: base, Schedule: []ScheduleEntry{{UpdatedAt: base.Add(-time.Hour)}, {UpdatedAt: base.Add(time.Minute)}}}
+	assert.Equal(t, base.Add(time.Minute), l.LastModified())
+
+	l.Schedule = nil
+	assert.Equal(t, base, l.LastModified())
+}
+
+func TestLoanSummarizeInstallments(t *testing.T) {
+	due := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+	l := &Loan{}
+	l.SummarizeInstallments([]ScheduleEntry{
+		{WeekNumber: 1, DueDate: due, DueAmount: 110, PaidAmount: 110, Status: PaymentStatusPaid},
+		{WeekNumber: 3, DueDate: due.AddDate(0, 0, 14), DueAmount: 110, Status: PaymentStatusPending},
+		{WeekNumber: 2, DueDate: due.AddDate(0, 0, 7), DueAmount: 110, PaidAmount: 40.1, Status: PaymentStatusMissed},
+	})
+	assert.Equal(t, due.AddDate(0, 0, 7), *l.NextDueDate)
+	assert.Equal(t, 69.9, l.NextDueAmount)
+	assert.Equal(t, 1, l.InstallmentsPaid)
+	assert.Equal(t, 2, l.InstallmentsRemaining)
+
+	l.SummarizeInstallments([]ScheduleEntry{{WeekNumber: 1, DueDate: due, Status: PaymentStatusPaid}})
+	assert.Nil(t, l.NextDueDate)
+	assert.Zero(t, l.NextDueAmount)
+	assert.Equal(t, 0, l.InstallmentsRemaining)
+}
+
+func BenchmarkGenerateSchedule(b *testing.B) {
+	start := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	for _, weeks := range []int{DefaultTermWeeks, 520} {
+		b.Run(fmt.Sprintf("weeks=%d", weeks), func(b *testing.B) {
+			loan, err := NewLoan(DefaultPrincipal, weeks, DefaultInterestRate, start)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := loan.GenerateSchedule(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
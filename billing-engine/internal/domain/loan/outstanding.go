@@ -0,0 +1,165 @@
+package loan
+
+import (
+	"math"
+	"time"
+)
+
+// BalanceItemKind classifies an amount held against a loan outside its
+// installment schedule.
+type BalanceItemKind string
+
+const (
+	BalanceFee     BalanceItemKind = "FEE"
+	BalancePenalty BalanceItemKind = "PENALTY"
+	// BalanceSuspense is money received for the loan that could not be
+	// applied to an installment, such as a direct-debit collection that
+	// arrived after the loan was paid off.
+	BalanceSuspense BalanceItemKind = "SUSPENSE"
+	BalanceCredit   BalanceItemKind = "CREDIT"
+	// BalanceTax is tax charged on a fee or on the interest that has
+	// accrued.
+	BalanceTax BalanceItemKind = "TAX"
+)
+
+// BalanceItem is one charge or holding that is not part of the schedule.
+// Fees, penalties and tax add to what is owed; suspense and credits reduce
+// it.
+type BalanceItem struct {
+	Kind        BalanceItemKind
+	Amount      Money
+	Description string
+}
+
+// OutstandingBreakdown itemizes what is owed on a loan as of a moment.
+// Every amount is rounded to the currency's minor unit and Total is never
+// negative.
+type OutstandingBreakdown struct {
+	LoanID int64
+	AsOf   time.Time
+	// TenantID is the tenant the breakdown was read for, empty when it was
+	// read unscoped. The outstanding cache serves it to that tenant only.
+	TenantID string
+
+	// Installments is what is left to pay on the unpaid installments. It
+	// splits into Principal and Interest, and into PastDue and the part
+	// that is not due yet.
+	Installments Money
+	Principal    Money
+	Interest     Money
+	// AccruedInterest is the interest in the unpaid installments whose due
+	// date has been reached.
+	AccruedInterest Money
+	// PastDue is what is left on the installments due before AsOf's day.
+	PastDue Money
+
+	Fees      Money
+	Penalties Money
+	// Tax is the tax on the open fees and on AccruedInterest. Interest on
+	// installments not yet due is not taxed until they fall due, so the tax
+	// is gone once every installment due is paid.
+	Tax      Money
+	Suspense Money
+	// Credits includes what settled installments were overpaid by within
+	// the payment tolerance.
+	Credits Money
+
+	Total Money
+
+	// Items are the balance items the fee, penalty, tax, suspense and
+	// credit totals were made from, ending with the tax on interest.
+	Items []BalanceItem
+}
+
+// CalculateOutstanding builds the breakdown from the loan's current schedule
+// and balance items. The interest share of each installment is taken from
+// the schedule rather than the loan's original totals, so it stays right
+// after the schedule is restructured. l.Prepayments must be complete: what
+// they repaid is principal the installments no longer carry. On an
+// interest-only or balloon loan the installments carry unequal shares of
+// principal, so each week's share is taken from its own principal instead.
+// taxes adds the tax on the accrued interest.
+func CalculateOutstanding(l *Loan, schedule []ScheduleEntry, items []BalanceItem, asOf time.Time, payments PaymentPolicy, taxes TaxPolicy) *OutstandingBreakdown {
+	b := &OutstandingBreakdown{LoanID: l.ID, AsOf: asOf, Items: make([]BalanceItem, 0, len(items)+1)}
+	b.Items = append(b.Items, items...)
+	today := truncateToDate(asOf)
+
+	var scheduled Money
+	for _, entry := range schedule {
+		scheduled += entry.DueAmount
+	}
+	principalShare := 1.0
+	if owed := l.PrincipalAmount - l.prepaid(); scheduled > 0 && owed < scheduled {
+		principalShare = owed / scheduled
+	}
+	shareOf := func(entry ScheduleEntry) float64 {
+		if !l.Structure.structured() {
+			return principalShare
+		}
+		if entry.DueAmount <= 0 {
+			return 1
+		}
+		return math.Min(1, l.structuredPrincipalOf(entry.WeekNumber)/entry.DueAmount)
+	}
+
+	var installments, principal, accrued, pastDue, overpaid Money
+	for _, entry := range schedule {
+		if entry.Status == PaymentStatusPaid {
+			if entry.PaidAmount > entry.DueAmount {
+				overpaid += entry.PaidAmount - entry.DueAmount
+			}
+			continue
+		}
+		left := entry.DueAmount - entry.PaidAmount
+		if left <= 0 {
+			continue
+		}
+		share := shareOf(entry)
+		installments += left
+		principal += left * share
+		due := truncateToDate(entry.DueDate)
+		if !due.After(today) {
+			accrued += left * (1 - share)
+		}
+		if due.Before(today) {
+			pastDue += left
+		}
+	}
+
+	b.Installments = payments.Round(installments)
+	b.Principal = payments.Round(principal)
+	b.Interest = payments.Round(b.Installments - b.Principal)
+	b.AccruedInterest = payments.Round(accrued)
+	b.PastDue = payments.Round(pastDue)
+	if item, ok := taxes.interestTax(b.AccruedInterest, payments.Round); ok {
+		b.Items = append(b.Items, item)
+	}
+
+	credits := overpaid
+	for _, item := range b.Items {
+		switch item.Kind {
+		case BalanceFee:
+			b.Fees += item.Amount
+		case BalancePenalty:
+			b.Penalties += item.Amount
+		case BalanceTax:
+			b.Tax += item.Amount
+		case BalanceSuspense:
+			b.Suspense += item.Amount
+		case BalanceCredit:
+			credits += item.Amount
+		}
+	}
+	b.Fees = payments.Round(b.Fees)
+	b.Penalties = payments.Round(b.Penalties)
+	b.Tax = payments.Round(b.Tax)
+	b.Suspense = payments.Round(b.Suspense)
+	b.Credits = payments.Round(credits)
+
+	total := payments.Round(b.Installments + b.Fees + b.Penalties + b.Tax - b.Suspense - b.Credits)
+	if total < 0 {
+		total = 0
+	}
+	b.Total = total
+	return b
+}
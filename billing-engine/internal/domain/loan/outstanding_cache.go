@@ -0,0 +1,250 @@
+package loan
+
+import (
+	"billing-engine/internal/pkg/clock"
+	"billing-engine/internal/pkg/scope"
+	"context"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// OutstandingCache keeps loans' outstanding breakdowns between the writes
+// that change them. A breakdown only holds for the day it was built on,
+// since installments fall due as days pass; the cache need not know that.
+type OutstandingCache interface {
+	// Get returns the breakdown stored for the loan, or false when there is
+	// none.
+	Get(ctx context.Context, loanID int64) (*OutstandingBreakdown, bool, error)
+	// Version returns a token that every Delete of the loan and every Clear
+	// moves on, wherever they ran.
+	Version(ctx context.Context, loanID int64) (string, error)
+	// Set stores the breakdown only if the loan's version still equals
+	// version, read before the breakdown was loaded, so a lookup that raced
+	// with a write does not store what it read.
+	Set(ctx context.Context, breakdown *OutstandingBreakdown, version string) error
+	Delete(ctx context.Context, loanID int64) error
+	// Clear deletes every breakdown, for writes that touch loans they do
+	// not name.
+	Clear(ctx context.Context) error
+}
+
+// versionStripes spreads the loans' versions so that a write on one loan
+// rarely keeps another's breakdown out of the memory cache.
+const versionStripes = 256
+
+// MemoryOutstandingCache keeps breakdowns in process for TTL, for
+// deployments without Redis. Each instance then only sees its own writes;
+// another instance's payment shows up once the entry expires.
+type MemoryOutstandingCache struct {
+	ttl   time.Duration
+	clock clock.Clock
+
+	mu       sync.Mutex
+	entries  map[int64]memoryOutstandingEntry
+	versions [versionStripes]uint64
+}
+
+type memoryOutstandingEntry struct {
+	breakdown OutstandingBreakdown
+	expires   time.Time
+}
+
+var _ OutstandingCache = (*MemoryOutstandingCache)(nil)
+
+// NewMemoryOutstandingCache expires entries by clk, nil meaning the wall
+// clock.
+func NewMemoryOutstandingCache(ttl time.Duration, clk clock.Clock) *MemoryOutstandingCache {
+	return &MemoryOutstandingCache{ttl: ttl, clock: clock.OrSystem(clk), entries: make(map[int64]memoryOutstandingEntry)}
+}
+
+func (c *MemoryOutstandingCache) Get(_ context.Context, loanID int64) (*OutstandingBreakdown, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[loanID]
+	if !ok {
+		return nil, false, nil
+	}
+	if !c.clock.Now().Before(entry.expires) {
+		delete(c.entries, loanID)
+		return nil, false, nil
+	}
+	breakdown := entry.breakdown
+	breakdown.Items = append([]BalanceItem(nil), entry.breakdown.Items...)
+	return &breakdown, true, nil
+}
+
+func (c *MemoryOutstandingCache) Version(_ context.Context, loanID int64) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return strconv.FormatUint(c.versions[stripe(loanID)], 10), nil
+}
+
+func (c *MemoryOutstandingCache) Set(_ context.Context, breakdown *OutstandingBreakdown, version string) error {
+	entry := memoryOutstandingEntry{breakdown: *breakdown, expires: c.clock.Now().Add(c.ttl)}
+	entry.breakdown.Items = append([]BalanceItem(nil), breakdown.Items...)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if strconv.FormatUint(c.versions[stripe(breakdown.LoanID)], 10) != version {
+		return nil
+	}
+	c.entries[breakdown.LoanID] = entry
+	return nil
+}
+
+func (c *MemoryOutstandingCache) Delete(_ context.Context, loanID int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.versions[stripe(loanID)]++
+	delete(c.entries, loanID)
+	return nil
+}
+
+func (c *MemoryOutstandingCache) Clear(context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := range c.versions {
+		c.versions[i]++
+	}
+	clear(c.entries)
+	return nil
+}
+
+func stripe(loanID int64) int {
+	return int(uint64(loanID) % versionStripes)
+}
+
+// cachingService answers outstanding lookups from an OutstandingCache and
+// drops a loan's entry after every write the wrapped service makes to it.
+// Each lookup reads the loan's version before loading and stores the
+// breakdown against it, so the cache turns it away if a write, on this
+// instance or another, came in since. A cache that fails is logged and
+// bypassed, so lookups fall back to reading the loan.
+type cachingService struct {
+	LoanService
+	cache  OutstandingCache
+	clock  clock.Clock
+	logger *slog.Logger
+}
+
+// NewCachingLoanService caches next's outstanding breakdowns in cache. clk
+// must be the clock next runs on, which tells the day a breakdown was built
+// on; nil means the wall clock.
+func NewCachingLoanService(next LoanService, cache OutstandingCache, clk clock.Clock, logger *slog.Logger) LoanService {
+	return &cachingService{LoanService: next, cache: cache, clock: clock.OrSystem(clk), logger: logger}
+}
+
+func (s *cachingService) GetOutstanding(ctx context.Context, loanID int64) (Money, error) {
+	breakdown, err := s.GetOutstandingBreakdown(ctx, loanID)
+	if err != nil {
+		return 0, err
+	}
+	return breakdown.Total, nil
+}
+
+// GetOutstandingBreakdown leaves customers' own lookups to the wrapped
+// service, which checks that the loan is theirs. A caller scoped to a tenant
+// is only served a breakdown read for that tenant; any other goes through
+// the wrapped service, which does not find another tenant's loan.
+func (s *cachingService) GetOutstandingBreakdown(ctx context.Context, loanID int64) (*OutstandingBreakdown, error) {
+	if _, scoped := scope.CustomerFromContext(ctx); scoped {
+		return s.LoanService.GetOutstandingBreakdown(ctx, loanID)
+	}
+	now := s.clock.Now()
+	cached, ok, err := s.cache.Get(ctx, loanID)
+	if err != nil {
+		s.logger.WarnContext(ctx, "Failed to read cached outstanding amount", "loanID", loanID, "error", err)
+	}
+	if tenantID, scoped := scope.TenantFromContext(ctx); ok && scoped && cached.TenantID != tenantID {
+		ok = false
+	}
+	if ok && truncateToDate(cached.AsOf).Equal(truncateToDate(now)) {
+		cached.AsOf = now
+		return cached, nil
+	}
+
+	version, versionErr := s.cache.Version(ctx, loanID)
+	if versionErr != nil {
+		s.logger.WarnContext(ctx, "Failed to read outstanding amount version", "loanID", loanID, "error", versionErr)
+	}
+	breakdown, err := s.LoanService.GetOutstandingBreakdown(ctx, loanID)
+	if err != nil {
+		return nil, err
+	}
+	if versionErr == nil {
+		if err := s.cache.Set(ctx, breakdown, version); err != nil {
+			s.logger.WarnContext(ctx, "Failed to cache outstanding amount", "loanID", loanID, "error", err)
+		}
+	}
+	return breakdown, nil
+}
+
+// invalidate drops the loan's breakdown. It runs whether the write
+// succeeded or not, since a write that returned an error may still have
+// been committed.
+func (s *cachingService) invalidate(ctx context.Context, loanID int64) {
+	if err := s.cache.Delete(ctx, loanID); err != nil {
+		s.logger.ErrorContext(ctx, "Failed to drop cached outstanding amount", "loanID", loanID, "error", err)
+	}
+}
+
+func (s *cachingService) invalidateAll(ctx context.Context) {
+	if err := s.cache.Clear(ctx); err != nil {
+		s.logger.ErrorContext(ctx, "Failed to clear cached outstanding amounts", "error", err)
+	}
+}
+
+func (s *cachingService) MakePayment(ctx context.Context, loanID int64, amount Money, details PaymentDetails) error {
+	defer s.invalidate(ctx, loanID)
+	return s.LoanService.MakePayment(ctx, loanID, amount, details)
+}
+
+func (s *cachingService) Prepay(ctx context.Context, loanID int64, amount Money, option PrepaymentOption, details PaymentDetails, recordedBy string) (*Reamortization, error) {
+	defer s.invalidate(ctx, loanID)
+	return s.LoanService.Prepay(ctx, loanID, amount, option, details, recordedBy)
+}
+
+func (s *cachingService) PostFee(ctx context.Context, loanID int64, feeType FeeType, amount Money, reason, postedBy string) (*Fee, error) {
+	defer s.invalidate(ctx, loanID)
+	return s.LoanService.PostFee(ctx, loanID, feeType, amount, reason, postedBy)
+}
+
+func (s *cachingService) ChargeLateFees(ctx context.Context, loanID int64, policy DelinquencyPolicy) ([]Fee, error) {
+	defer s.invalidate(ctx, loanID)
+	return s.LoanService.ChargeLateFees(ctx, loanID, policy)
+}
+
+func (s *cachingService) DecideFeeWaiver(ctx context.Context, loanID, feeID int64, approve bool, decidedBy string) (*FeeWaiver, error) {
+	defer s.invalidate(ctx, loanID)
+	return s.LoanService.DecideFeeWaiver(ctx, loanID, feeID, approve, decidedBy)
+}
+
+func (s *cachingService) RebuildSchedule(ctx context.Context, loanID int64, dryRun bool) (*ScheduleRebuild, error) {
+	if !dryRun {
+		defer s.invalidate(ctx, loanID)
+	}
+	return s.LoanService.RebuildSchedule(ctx, loanID, dryRun)
+}
+
+func (s *cachingService) RepriceLoan(ctx context.Context, loanID int64, rate float64, effectiveFrom time.Time, changedBy string) (*Repricing, error) {
+	defer s.invalidate(ctx, loanID)
+	return s.LoanService.RepriceLoan(ctx, loanID, rate, effectiveFrom, changedBy)
+}
+
+// RepriceLoans does not report which loans it repriced, so every breakdown
+// goes.
+func (s *cachingService) RepriceLoans(ctx context.Context, filter RepricingFilter, rate float64, effectiveFrom time.Time, changedBy string) (*RepricingReport, error) {
+	defer s.invalidateAll(ctx)
+	return s.LoanService.RepriceLoans(ctx, filter, rate, effectiveFrom, changedBy)
+}
+
+func (s *cachingService) ApplyScheduleAdjustment(ctx context.Context, loanID int64, adjustment ScheduleAdjustment, appliedBy string) (*AdjustmentPlan, error) {
+	defer s.invalidate(ctx, loanID)
+	return s.LoanService.ApplyScheduleAdjustment(ctx, loanID, adjustment, appliedBy)
+}
+
+func (s *cachingService) RemoveScheduleAdjustment(ctx context.Context, loanID, adjustmentID int64, removedBy string) (*AdjustmentPlan, error) {
+	defer s.invalidate(ctx, loanID)
+	return s.LoanService.RemoveScheduleAdjustment(ctx, loanID, adjustmentID, removedBy)
+}
@@ -0,0 +1,235 @@
+package loan
+
+import (
+	"billing-engine/internal/pkg/clock"
+	"billing-engine/internal/pkg/scope"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingLoanService owes total on every loan and counts the breakdowns it
+// builds. during runs inside a lookup, to stand in for a concurrent write.
+type countingLoanService struct {
+	LoanService
+	clock   clock.Clock
+	total   Money
+	lookups int
+	during  func()
+}
+
+func (s *countingLoanService) GetOutstandingBreakdown(ctx context.Context, loanID int64) (*OutstandingBreakdown, error) {
+	s.lookups++
+	if s.during != nil {
+		s.during()
+	}
+	tenantID, _ := scope.TenantFromContext(ctx)
+	return &OutstandingBreakdown{LoanID: loanID, AsOf: s.clock.Now(), TenantID: tenantID, Total: s.total,
+		Items: []BalanceItem{{Kind: BalanceFee, Amount: 5}}}, nil
+}
+
+func (s *countingLoanService) MakePayment(_ context.Context, _ int64, amount Money, _ PaymentDetails) error {
+	s.total -= amount
+	return nil
+}
+
+func (s *countingLoanService) PostFee(context.Context, int64, FeeType, Money, string, string) (*Fee, error) {
+	return nil, errors.New("db")
+}
+
+func (s *countingLoanService) RepriceLoans(context.Context, RepricingFilter, float64, time.Time, string) (*RepricingReport, error) {
+	s.total += 10
+	return &RepricingReport{}, nil
+}
+
+// failingOutstandingCache stands in for an unreachable Redis.
+type failingOutstandingCache struct{}
+
+func (failingOutstandingCache) Get(context.Context, int64) (*OutstandingBreakdown, bool, error) {
+	return nil, false, errors.New("connection refused")
+}
+
+func (failingOutstandingCache) Version(context.Context, int64) (string, error) {
+	return "", errors.New("connection refused")
+}
+
+func (failingOutstandingCache) Set(context.Context, *OutstandingBreakdown, string) error {
+	return errors.New("connection refused")
+}
+
+func (failingOutstandingCache) Delete(context.Context, int64) error {
+	return errors.New("connection refused")
+}
+
+func (failingOutstandingCache) Clear(context.Context) error { return errors.New("connection refused") }
+
+func TestCachingLoanService(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	setup := func() (*countingLoanService, LoanService, *clock.Fake) {
+		clk := clock.NewFake(time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC))
+		next := &countingLoanService{clock: clk, total: 100}
+		return next, NewCachingLoanService(next, NewMemoryOutstandingCache(time.Hour, clk), clk, logger), clk
+	}
+
+	t.Run("serves repeated lookups from the cache", func(t *testing.T) {
+		next, svc, clk := setup()
+		total, err := svc.GetOutstanding(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, 100.0, total)
+
+		clk.Advance(time.Minute)
+		breakdown, err := svc.GetOutstandingBreakdown(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, 100.0, breakdown.Total)
+		assert.Equal(t, clk.Now(), breakdown.AsOf)
+		assert.Equal(t, 1, next.lookups)
+
+		breakdown.Items[0].Amount = 0
+		again, err := svc.GetOutstandingBreakdown(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, 5.0, again.Items[0].Amount, "callers do not share the cached items")
+	})
+
+	t.Run("a write drops the loan's entry", func(t *testing.T) {
+		next, svc, _ := setup()
+		_, err := svc.GetOutstanding(ctx, 1)
+		require.NoError(t, err)
+		_, err = svc.GetOutstanding(ctx, 2)
+		require.NoError(t, err)
+
+		require.NoError(t, svc.MakePayment(ctx, 1, 40, PaymentDetails{}))
+		total, err := svc.GetOutstanding(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, 60.0, total)
+		_, err = svc.GetOutstanding(ctx, 2)
+		require.NoError(t, err)
+		assert.Equal(t, 3, next.lookups, "loan 2 is still cached")
+	})
+
+	t.Run("a failed write drops the entry too", func(t *testing.T) {
+		next, svc, _ := setup()
+		_, err := svc.GetOutstanding(ctx, 1)
+		require.NoError(t, err)
+		_, err = svc.PostFee(ctx, 1, FeeBounce, 5, "", "admin")
+		assert.Error(t, err)
+		_, err = svc.GetOutstanding(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, 2, next.lookups)
+	})
+
+	t.Run("repricing many loans clears the cache", func(t *testing.T) {
+		next, svc, _ := setup()
+		_, err := svc.GetOutstanding(ctx, 1)
+		require.NoError(t, err)
+		_, err = svc.RepriceLoans(ctx, RepricingFilter{}, 0.1, time.Time{}, "admin")
+		require.NoError(t, err)
+		total, err := svc.GetOutstanding(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, 110.0, total)
+		assert.Equal(t, 2, next.lookups)
+	})
+
+	t.Run("an entry from an earlier day is not served", func(t *testing.T) {
+		next, svc, clk := setup()
+		_, err := svc.GetOutstanding(ctx, 1)
+		require.NoError(t, err)
+		clk.Advance(15 * time.Hour)
+		_, err = svc.GetOutstanding(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, 2, next.lookups)
+	})
+
+	t.Run("a lookup that raced with a write is not cached", func(t *testing.T) {
+		next, svc, _ := setup()
+		next.during = func() {
+			next.during = nil
+			require.NoError(t, svc.MakePayment(ctx, 1, 40, PaymentDetails{}))
+		}
+		_, err := svc.GetOutstanding(ctx, 1)
+		require.NoError(t, err)
+		total, err := svc.GetOutstanding(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, 60.0, total)
+		assert.Equal(t, 2, next.lookups)
+	})
+
+	t.Run("a write on another instance keeps a racing lookup out of the shared cache", func(t *testing.T) {
+		clk := clock.NewFake(time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC))
+		next := &countingLoanService{clock: clk, total: 100}
+		cache := NewMemoryOutstandingCache(time.Hour, clk)
+		svc := NewCachingLoanService(next, cache, clk, logger)
+		other := NewCachingLoanService(next, cache, clk, logger)
+		next.during = func() {
+			next.during = nil
+			require.NoError(t, other.MakePayment(ctx, 1, 40, PaymentDetails{}))
+		}
+		_, err := svc.GetOutstanding(ctx, 1)
+		require.NoError(t, err)
+		total, err := svc.GetOutstanding(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, 60.0, total)
+		assert.Equal(t, 2, next.lookups)
+	})
+
+	t.Run("customers' own lookups bypass the cache", func(t *testing.T) {
+		next, svc, _ := setup()
+		customerCtx := scope.WithCustomer(ctx, 7)
+		_, err := svc.GetOutstanding(customerCtx, 1)
+		require.NoError(t, err)
+		_, err = svc.GetOutstanding(customerCtx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, 2, next.lookups)
+	})
+
+	t.Run("serves an entry to the tenant it was read for only", func(t *testing.T) {
+		next, svc, _ := setup()
+		tenantA := scope.WithTenant(ctx, "tenant-a")
+		tenantB := scope.WithTenant(ctx, "tenant-b")
+		_, err := svc.GetOutstanding(tenantA, 1)
+		require.NoError(t, err)
+		_, err = svc.GetOutstanding(tenantA, 1)
+		require.NoError(t, err)
+		assert.Equal(t, 1, next.lookups)
+
+		breakdown, err := svc.GetOutstandingBreakdown(tenantB, 1)
+		require.NoError(t, err)
+		assert.Equal(t, 2, next.lookups, "another tenant's lookup goes to the loan")
+		assert.Equal(t, "tenant-b", breakdown.TenantID)
+	})
+
+	t.Run("falls back to the loan when the cache fails", func(t *testing.T) {
+		clk := clock.NewFake(time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC))
+		next := &countingLoanService{clock: clk, total: 100}
+		svc := NewCachingLoanService(next, failingOutstandingCache{}, clk, logger)
+		total, err := svc.GetOutstanding(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, 100.0, total)
+		require.NoError(t, svc.MakePayment(ctx, 1, 40, PaymentDetails{}))
+	})
+}
+
+func TestMemoryOutstandingCacheExpires(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC))
+	cache := NewMemoryOutstandingCache(time.Minute, clk)
+	version, err := cache.Version(ctx, 1)
+	require.NoError(t, err)
+	require.NoError(t, cache.Set(ctx, &OutstandingBreakdown{LoanID: 1, Total: 100}, version))
+
+	clk.Advance(59 * time.Second)
+	_, ok, err := cache.Get(ctx, 1)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	clk.Advance(time.Second)
+	_, ok, err = cache.Get(ctx, 1)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,146 @@
+package loan
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculateOutstanding(t *testing.T) {
+	day := func(s string) time.Time {
+		d, _ := time.Parse(time.DateOnly, s)
+		return d
+	}
+	l := &Loan{ID: 5, PrincipalAmount: 400, TotalLoanAmount: 440}
+	schedule := []ScheduleEntry{
+		{WeekNumber: 1, DueDate: day("2025-01-06"), DueAmount: 110, PaidAmount: 110.5, Status: PaymentStatusPaid},
+		{WeekNumber: 2, DueDate: day("2025-01-13"), DueAmount: 110, Status: PaymentStatusPending},
+		{WeekNumber: 3, DueDate: day("2025-01-20"), DueAmount: 110, PaidAmount: 10, Status: PaymentStatusPending},
+		{WeekNumber: 4, DueDate: day("2025-01-27"), DueAmount: 110, Status: PaymentStatusPending},
+	}
+	asOf := day("2025-01-20").Add(9 * time.Hour)
+
+	t.Run("itemizes the schedule and balance items", func(t *testing.T) {
+		items := []BalanceItem{
+			{Kind: BalanceFee, Amount: 25, Description: "late fee"},
+			{Kind: BalancePenalty, Amount: 5},
+			{Kind: BalanceSuspense, Amount: 110},
+			{Kind: BalanceCredit, Amount: 1.5},
+		}
+
+		b := CalculateOutstanding(l, schedule, items, asOf, DefaultPaymentPolicy(), TaxPolicy{})
+
+		assert.Equal(t, int64(5), b.LoanID)
+		assert.Equal(t, 320.0, b.Installments)
+		assert.Equal(t, 290.91, b.Principal)
+		assert.Equal(t, 29.09, b.Interest)
+		assert.Equal(t, 19.09, b.AccruedInterest, "installments due today have accrued")
+		assert.Equal(t, 110.0, b.PastDue, "installments due today are not past due")
+		assert.Equal(t, 25.0, b.Fees)
+		assert.Equal(t, 5.0, b.Penalties)
+		assert.Equal(t, 110.0, b.Suspense)
+		assert.Equal(t, 2.0, b.Credits, "overpaid installment plus the credit item")
+		assert.Equal(t, 238.0, b.Total)
+		assert.Len(t, b.Items, 4)
+	})
+
+	t.Run("adds the tax on fees and on the accrued interest", func(t *testing.T) {
+		taxes, err := NewTaxPolicy("ID", map[string]TaxRates{"ID": {Interest: 0.11}})
+		assert.NoError(t, err)
+		items := []BalanceItem{
+			{Kind: BalanceFee, Amount: 25, Description: "Bounce fee: returned"},
+			{Kind: BalanceTax, Amount: 2.75, Description: "Tax on Bounce fee: returned"},
+		}
+
+		b := CalculateOutstanding(l, schedule, items, asOf, DefaultPaymentPolicy(), taxes)
+
+		assert.Equal(t, 4.85, b.Tax, "2.75 on the fee and 11% of 19.09 accrued interest")
+		assert.Equal(t, 349.35, b.Total)
+		assert.Equal(t, BalanceItem{Kind: BalanceTax, Amount: 2.1, Description: "Tax on interest (ID 11%)"}, b.Items[2])
+		assert.Len(t, items, 2, "the caller's items are left alone")
+	})
+
+	t.Run("taxes no interest once every installment due is paid", func(t *testing.T) {
+		taxes, err := NewTaxPolicy("ID", map[string]TaxRates{"ID": {Interest: 0.11}})
+		assert.NoError(t, err)
+		current := []ScheduleEntry{
+			{WeekNumber: 1, DueDate: day("2025-01-13"), DueAmount: 110, PaidAmount: 110, Status: PaymentStatusPaid},
+			{WeekNumber: 2, DueDate: day("2025-01-20"), DueAmount: 110, PaidAmount: 110, Status: PaymentStatusPaid},
+			{WeekNumber: 3, DueDate: day("2025-01-27"), DueAmount: 110, Status: PaymentStatusPending},
+		}
+
+		b := CalculateOutstanding(l, current, nil, asOf, DefaultPaymentPolicy(), taxes)
+
+		assert.Zero(t, b.Tax)
+		assert.Equal(t, b.Installments, b.Total, "paying the installments settles the loan")
+		assert.Len(t, b.Items, 0)
+	})
+
+	t.Run("never owes less than nothing", func(t *testing.T) {
+		b := CalculateOutstanding(l, schedule, []BalanceItem{{Kind: BalanceSuspense, Amount: 500}}, asOf, DefaultPaymentPolicy(), TaxPolicy{})
+
+		assert.Zero(t, b.Total)
+		assert.Equal(t, 500.0, b.Suspense)
+	})
+
+	t.Run("uses the current schedule after a restructure", func(t *testing.T) {
+		restructured := []ScheduleEntry{
+			{WeekNumber: 1, DueDate: day("2025-02-03"), DueAmount: 250, Status: PaymentStatusPending},
+			{WeekNumber: 2, DueDate: day("2025-02-10"), DueAmount: 250, Status: PaymentStatusPending},
+		}
+
+		b := CalculateOutstanding(l, restructured, nil, asOf, DefaultPaymentPolicy(), TaxPolicy{})
+
+		assert.Equal(t, 500.0, b.Total)
+		assert.Equal(t, 400.0, b.Principal)
+		assert.Equal(t, 100.0, b.Interest)
+		assert.Zero(t, b.AccruedInterest)
+		assert.NotNil(t, b.Items)
+	})
+	t.Run("leaves prepaid principal out of the installments", func(t *testing.T) {
+		prepaid := &Loan{ID: 6, PrincipalAmount: 300, TotalLoanAmount: 325, Prepayments: []Prepayment{{Amount: 50, AfterWeek: 1}}}
+		reamortized := []ScheduleEntry{
+			{WeekNumber: 1, DueDate: day("2025-01-13"), DueAmount: 110, PaidAmount: 110, Status: PaymentStatusPaid},
+			{WeekNumber: 2, DueDate: day("2025-01-20"), DueAmount: 82.5, Status: PaymentStatusPending},
+			{WeekNumber: 3, DueDate: day("2025-01-27"), DueAmount: 82.5, Status: PaymentStatusPending},
+		}
+
+		b := CalculateOutstanding(prepaid, reamortized, nil, asOf, DefaultPaymentPolicy(), TaxPolicy{})
+
+		assert.Equal(t, 165.0, b.Installments)
+		assert.Equal(t, 150.0, b.Principal)
+		assert.Equal(t, 15.0, b.Interest)
+	})
+}
+
+// BenchmarkCalculateOutstanding itemizes a default loan halfway through its
+// term, with fees and tax on interest, as GET /loans/{loanID}/outstanding
+// does on every uncached request.
+func BenchmarkCalculateOutstanding(b *testing.B) {
+	start := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	l, err := NewLoan(DefaultPrincipal, DefaultTermWeeks, DefaultInterestRate, start)
+	if err != nil {
+		b.Fatal(err)
+	}
+	schedule, err := l.GenerateSchedule()
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := range schedule[:DefaultTermWeeks/2] {
+		schedule[i].PaidAmount, schedule[i].Status = schedule[i].DueAmount, PaymentStatusPaid
+	}
+	items := []BalanceItem{
+		{Kind: BalanceFee, Amount: 25_000, Description: "LATE"},
+		{Kind: BalanceFee, Amount: 15_000, Description: "BOUNCE"},
+		{Kind: BalanceTax, Amount: 4_400},
+		{Kind: BalanceSuspense, Amount: 10_000},
+	}
+	taxes := TaxPolicy{Jurisdiction: "ID", Rates: map[string]TaxRates{"ID": {Interest: 0.11}}}
+	asOf := start.AddDate(0, 0, 7*DefaultTermWeeks/2+3)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		CalculateOutstanding(l, schedule, items, asOf, DefaultPaymentPolicy(), taxes)
+	}
+}
@@ -0,0 +1,130 @@
+package loan
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// PaymentChannel is how the money of a payment reached the lender.
+type PaymentChannel string
+
+const (
+	ChannelCash         PaymentChannel = "CASH"
+	ChannelBankTransfer PaymentChannel = "BANK_TRANSFER"
+	ChannelGateway      PaymentChannel = "GATEWAY"
+	ChannelDirectDebit  PaymentChannel = "DIRECT_DEBIT"
+	// ChannelUnspecified is recorded for payments posted without a channel,
+	// including every payment made before channels were tracked.
+	ChannelUnspecified PaymentChannel = "UNSPECIFIED"
+)
+
+// PaymentChannels lists the channels in the order reports show them.
+var PaymentChannels = []PaymentChannel{
+	ChannelCash, ChannelBankTransfer, ChannelGateway, ChannelDirectDebit, ChannelUnspecified,
+}
+
+const (
+	maxPaymentReferenceLength = 100
+	maxCollectorIDLength      = 64
+)
+
+// PaymentDetails says where a payment came from, for reconciliation.
+type PaymentDetails struct {
+	Channel PaymentChannel
+	// Reference is the identifier the channel assigned, such as the bank
+	// transfer reference, the gateway transaction ID or the receipt number.
+	// It is unique per channel, so a payment cannot be posted twice.
+	Reference string
+	// CollectorID identifies the field agent who took a cash payment.
+	CollectorID string
+}
+
+func (d *PaymentDetails) normalize() {
+	d.Channel = PaymentChannel(strings.ToUpper(strings.TrimSpace(string(d.Channel))))
+	if d.Channel == "" {
+		d.Channel = ChannelUnspecified
+	}
+	d.Reference = strings.TrimSpace(d.Reference)
+	d.CollectorID = strings.TrimSpace(d.CollectorID)
+}
+
+// Validate checks the details after trimming them; an empty channel stands
+// for ChannelUnspecified.
+func (d PaymentDetails) Validate() error {
+	d.normalize()
+	if !slices.Contains(PaymentChannels, d.Channel) {
+		return fmt.Errorf("%w: unknown payment channel %q, use CASH, BANK_TRANSFER, GATEWAY or DIRECT_DEBIT", apperrors.ErrInvalidArgument, d.Channel)
+	}
+	if len(d.Reference) > maxPaymentReferenceLength {
+		return fmt.Errorf("%w: payment reference must be at most %d characters", apperrors.ErrInvalidArgument, maxPaymentReferenceLength)
+	}
+	if len(d.CollectorID) > maxCollectorIDLength {
+		return fmt.Errorf("%w: collector ID must be at most %d characters", apperrors.ErrInvalidArgument, maxCollectorIDLength)
+	}
+	return nil
+}
+
+// Payment is an entry of the payments ledger: one payment applied to one
+// installment or, once every installment is paid, to one fee with its tax.
+// Exactly one of ScheduleID and FeeID is set.
+type Payment struct {
+	ID          int64
+	LoanID      int64
+	ScheduleID  *int64
+	FeeID       *int64
+	Amount      Money
+	Channel     PaymentChannel
+	Reference   *string
+	CollectorID *string
+	PaidAt      time.Time
+	CreatedAt   time.Time
+}
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// ChannelCollections totals the payments received through one channel.
+type ChannelCollections struct {
+	Channel  PaymentChannel
+	Payments int
+	Amount   Money
+}
+
+// CollectionsReport totals the payments received between From and To,
+// inclusive, by channel. Every channel is listed, with zeros if nothing came
+// through it.
+type CollectionsReport struct {
+	From          time.Time
+	To            time.Time
+	Channels      []ChannelCollections
+	TotalPayments int
+	TotalAmount   Money
+}
+
+// newCollectionsReport fills in the channels the repository returned no row
+// for and the totals.
+func newCollectionsReport(from, to time.Time, rows []ChannelCollections, round func(Money) Money) *CollectionsReport {
+	byChannel := make(map[PaymentChannel]ChannelCollections, len(rows))
+	for _, row := range rows {
+		byChannel[row.Channel] = row
+	}
+	report := &CollectionsReport{From: from, To: to, Channels: make([]ChannelCollections, 0, len(PaymentChannels))}
+	var total Money
+	for _, c := range PaymentChannels {
+		row := byChannel[c]
+		row.Channel = c
+		row.Amount = round(row.Amount)
+		report.Channels = append(report.Channels, row)
+		report.TotalPayments += row.Payments
+		total += row.Amount
+	}
+	report.TotalAmount = round(total)
+	return report
+}
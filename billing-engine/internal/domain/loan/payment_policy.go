@@ -0,0 +1,93 @@
+package loan
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"fmt"
+	"math"
+
+	"github.com/shopspring/decimal"
+)
+
+type RoundingMode string
+
+const (
+	RoundHalfUp   RoundingMode = "half_up"
+	RoundHalfEven RoundingMode = "half_even"
+	RoundDown     RoundingMode = "down"
+)
+
+// PaymentPolicy decides whether a payment settles the installment it is
+// applied to.
+type PaymentPolicy struct {
+	// MinorUnitDigits is how many decimals the currency has: 2 for IDR and
+	// USD, 0 for JPY. Payments and installments are rounded to it.
+	MinorUnitDigits int
+	// Tolerance is the largest difference between the rounded payment and
+	// the rounded amount due that is still accepted.
+	Tolerance float64
+	Rounding  RoundingMode
+}
+
+// DefaultPaymentPolicy accepts payments that match the installment to the
+// cent.
+func DefaultPaymentPolicy() PaymentPolicy {
+	return PaymentPolicy{MinorUnitDigits: 2, Tolerance: 0.001, Rounding: RoundHalfUp}
+}
+
+// NewPaymentPolicy validates the configured values.
+func NewPaymentPolicy(minorUnitDigits int, tolerance float64, rounding string) (PaymentPolicy, error) {
+	if minorUnitDigits < 0 || minorUnitDigits > 4 {
+		return PaymentPolicy{}, fmt.Errorf("%w: minor unit digits must be between 0 and 4, got %d", apperrors.ErrInvalidArgument, minorUnitDigits)
+	}
+	if tolerance < 0 || math.IsNaN(tolerance) {
+		return PaymentPolicy{}, fmt.Errorf("%w: payment tolerance must not be negative", apperrors.ErrInvalidArgument)
+	}
+	mode := RoundingMode(rounding)
+	switch mode {
+	case RoundHalfUp, RoundHalfEven, RoundDown:
+	default:
+		return PaymentPolicy{}, fmt.Errorf("%w: unknown rounding mode %q, use %q, %q or %q",
+			apperrors.ErrInvalidArgument, rounding, RoundHalfUp, RoundHalfEven, RoundDown)
+	}
+	return PaymentPolicy{MinorUnitDigits: minorUnitDigits, Tolerance: tolerance, Rounding: mode}, nil
+}
+
+// Round rounds amount to the minor unit of the currency.
+func (p PaymentPolicy) Round(amount Money) Money {
+	d := decimal.NewFromFloat(amount)
+	places := int32(p.MinorUnitDigits)
+	switch p.Rounding {
+	case RoundHalfEven:
+		d = d.RoundBank(places)
+	case RoundDown:
+		d = d.Truncate(places)
+	default:
+		d = d.Round(places)
+	}
+	f, _ := d.Float64()
+	return f
+}
+
+// Expected is what is left to pay on the installment after earlier partial
+// payments, rounded to the minor unit.
+func (p PaymentPolicy) Expected(entry *ScheduleEntry) Money {
+	return p.Round(entry.DueAmount - entry.PaidAmount)
+}
+
+// Check returns an *apperrors.PaymentAmountError carrying the expected
+// amount when amount does not settle entry.
+func (p PaymentPolicy) Check(entry *ScheduleEntry, amount Money) error {
+	return p.check(p.Expected(entry), amount)
+}
+
+// CheckFee is Check for a fee, which amount must settle with its tax.
+func (p PaymentPolicy) CheckFee(fee *Fee, amount Money) error {
+	return p.check(p.Round(fee.Owed()), amount)
+}
+
+func (p PaymentPolicy) check(expected, amount Money) error {
+	if math.Abs(p.Round(amount)-expected) > p.Tolerance {
+		return &apperrors.PaymentAmountError{Amount: amount, Expected: expected, Digits: p.MinorUnitDigits}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package loan
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPaymentPolicy(t *testing.T) {
+	policy, err := NewPaymentPolicy(0, 0.5, "half_even")
+	require.NoError(t, err)
+	assert.Equal(t, PaymentPolicy{MinorUnitDigits: 0, Tolerance: 0.5, Rounding: RoundHalfEven}, policy)
+
+	_, err = NewPaymentPolicy(-1, 0, "half_up")
+	assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+	_, err = NewPaymentPolicy(2, -0.01, "half_up")
+	assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+	_, err = NewPaymentPolicy(2, 0, "ceiling")
+	assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+}
+
+func TestPaymentPolicyRound(t *testing.T) {
+	tests := []struct {
+		mode   RoundingMode
+		digits int
+		in     Money
+		want   Money
+	}{
+		{RoundHalfUp, 2, 1.005, 1.01},
+		{RoundHalfUp, 2, 110.004, 110},
+		{RoundHalfEven, 2, 0.125, 0.12},
+		{RoundHalfEven, 2, 0.135, 0.14},
+		{RoundDown, 2, 99.999, 99.99},
+		{RoundHalfUp, 0, 1500.5, 1501},
+		{RoundDown, 0, 1500.9, 1500},
+	}
+	for _, tt := range tests {
+		policy := PaymentPolicy{MinorUnitDigits: tt.digits, Rounding: tt.mode}
+		assert.Equal(t, tt.want, policy.Round(tt.in), "%s to %d digits of %v", tt.mode, tt.digits, tt.in)
+	}
+}
+
+func TestPaymentPolicyCheck(t *testing.T) {
+	policy := DefaultPaymentPolicy()
+
+	t.Run("accepts the amount due", func(t *testing.T) {
+		assert.NoError(t, policy.Check(&ScheduleEntry{DueAmount: 110}, 110))
+		assert.NoError(t, policy.Check(&ScheduleEntry{DueAmount: 110}, 110.004), "differences below a cent round away")
+	})
+
+	t.Run("expects what is left after a partial payment", func(t *testing.T) {
+		entry := &ScheduleEntry{DueAmount: 110, PaidAmount: 40}
+		assert.NoError(t, policy.Check(entry, 70))
+
+		err := policy.Check(entry, 110)
+		var amountErr *apperrors.PaymentAmountError
+		require.True(t, errors.As(err, &amountErr))
+		assert.ErrorIs(t, err, apperrors.ErrInvalidPaymentAmount)
+		assert.Equal(t, Money(70), amountErr.Expected)
+		assert.Equal(t, "70.00", amountErr.ExpectedString())
+	})
+
+	t.Run("honours the configured tolerance", func(t *testing.T) {
+		lenient := PaymentPolicy{MinorUnitDigits: 0, Tolerance: 1, Rounding: RoundHalfUp}
+		assert.NoError(t, lenient.Check(&ScheduleEntry{DueAmount: 11000}, 10999))
+		err := lenient.Check(&ScheduleEntry{DueAmount: 11000}, 10998)
+		assert.EqualError(t, err, "invalid payment amount: payment amount 10998 does not match due amount 11000")
+	})
+}
@@ -0,0 +1,238 @@
+package loan
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// PrepaymentOption is what the borrower chose to do with a lump sum paid on
+// top of the installments.
+type PrepaymentOption string
+
+const (
+	// PrepaymentReduceTerm keeps the installment and drops the weeks the
+	// prepaid principal no longer needs.
+	PrepaymentReduceTerm PrepaymentOption = "REDUCE_TERM"
+	// PrepaymentReduceInstallment keeps the term and spreads the remaining
+	// principal over the weeks left.
+	PrepaymentReduceInstallment PrepaymentOption = "REDUCE_INSTALLMENT"
+)
+
+// Prepayment is a lump sum paid against a loan's principal, and the terms its
+// unpaid installments were reamortized to. Interest is charged on the
+// principal each installment repays, so prepaying lowers the interest of the
+// weeks after it.
+type Prepayment struct {
+	ID     int64
+	LoanID int64
+	Option PrepaymentOption
+	Amount Money
+	// AfterWeek is how many installments were paid when the lump sum came in;
+	// the schedule is reamortized from the week after.
+	AfterWeek int
+	// Principal is what was left to repay after the lump sum. Every
+	// installment from AfterWeek+1 on repays WeeklyPrincipal of it, except the
+	// last, which repays the rest.
+	Principal       Money
+	WeeklyPrincipal Money
+	// The loan terms before and after.
+	PreviousTermWeeks           int
+	PreviousWeeklyPaymentAmount Money
+	PreviousTotalLoanAmount     Money
+	TermWeeks                   int
+	WeeklyPaymentAmount         Money
+	TotalLoanAmount             Money
+	Channel                     PaymentChannel
+	Reference                   *string
+	RecordedBy                  *string
+	PaidAt                      time.Time
+}
+
+// Reamortization is a prepayment together with the installments it
+// recalculates, adds back or drops.
+type Reamortization struct {
+	Prepayment Prepayment
+	Changes    []ScheduleChange
+}
+
+// prepaid is the principal the loan's lump sums repaid.
+func (l *Loan) prepaid() Money {
+	var total Money
+	for _, p := range l.Prepayments {
+		total += p.Amount
+	}
+	return roundTo(total, 2)
+}
+
+// bookedTermWeeks is the term the loan was booked with, before any
+// prepayment shortened it.
+func (l *Loan) bookedTermWeeks() int {
+	if len(l.Prepayments) > 0 {
+		return l.Prepayments[0].PreviousTermWeeks
+	}
+	return l.TermWeeks
+}
+
+// prepaymentBefore returns the latest prepayment that reamortized week, or
+// nil when the week still has its booked terms.
+func (l *Loan) prepaymentBefore(week int) *Prepayment {
+	for i := len(l.Prepayments) - 1; i >= 0; i-- {
+		if l.Prepayments[i].AfterWeek < week {
+			return &l.Prepayments[i]
+		}
+	}
+	return nil
+}
+
+// regularPrincipal is the principal the loan's regular installment repays
+// under its latest terms.
+func (l *Loan) regularPrincipal() Money {
+	if n := len(l.Prepayments); n > 0 {
+		return l.Prepayments[n-1].WeeklyPrincipal
+	}
+	return l.PrincipalAmount / float64(l.TermWeeks)
+}
+
+// principalOf is the principal installment week repays.
+func (l *Loan) principalOf(week int) Money {
+	if l.Structure.structured() {
+		return l.structuredPrincipalOf(week)
+	}
+	p := l.prepaymentBefore(week)
+	if p == nil {
+		return l.PrincipalAmount / float64(l.bookedTermWeeks())
+	}
+	if week == l.TermWeeks {
+		return p.Principal - float64(l.TermWeeks-p.AfterWeek-1)*p.WeeklyPrincipal
+	}
+	return p.WeeklyPrincipal
+}
+
+func validatePrepayment(amount Money, option PrepaymentOption) (Money, error) {
+	switch option {
+	case PrepaymentReduceTerm, PrepaymentReduceInstallment:
+	default:
+		return 0, fmt.Errorf("%w: prepayment option must be %s or %s", apperrors.ErrInvalidArgument, PrepaymentReduceTerm, PrepaymentReduceInstallment)
+	}
+	if math.IsNaN(amount) {
+		return 0, fmt.Errorf("%w: prepayment amount must be positive", apperrors.ErrInvalidArgument)
+	}
+	if amount = roundTo(amount, 2); amount <= 0 {
+		return 0, fmt.Errorf("%w: prepayment amount must be positive", apperrors.ErrInvalidArgument)
+	}
+	return amount, nil
+}
+
+// PlanPrepayment works out what a lump sum of amount, rounded to cents, does
+// to l, whose RateHistory, Adjustments and Prepayments must be complete, and
+// to current, its stored schedule, sorted by week. today is the billing
+// date. The loan must be current: not paid off or delinquent, and with no
+// unpaid installment due before today. The lump sum must leave principal to
+// repay; settling the loan is done by paying its installments.
+func PlanPrepayment(l *Loan, current []ScheduleEntry, amount Money, option PrepaymentOption, today time.Time) (*Reamortization, error) {
+	amount, err := validatePrepayment(amount, option)
+	if err != nil {
+		return nil, err
+	}
+	switch l.Status {
+	case StatusPaidOff:
+		return nil, fmt.Errorf("%w: loan %d is paid off", apperrors.ErrConflict, l.ID)
+	case StatusDelinquent:
+		return nil, fmt.Errorf("%w: loan %d is delinquent; a prepayment needs the arrears paid first", apperrors.ErrConflict, l.ID)
+	}
+	if err := l.checkReshapable("a prepayment"); err != nil {
+		return nil, err
+	}
+
+	paidWeeks := 0
+	for paidWeeks < len(current) && current[paidWeeks].hasPayment() {
+		paidWeeks++
+	}
+	if paidWeeks >= l.TermWeeks {
+		return nil, fmt.Errorf("%w: loan %d has no installment left to reamortize", apperrors.ErrConflict, l.ID)
+	}
+	if paidWeeks < len(current) {
+		if next := &current[paidWeeks]; truncateToDate(next.DueDate).Before(truncateToDate(today)) {
+			return nil, fmt.Errorf("%w: week %d of loan %d was due on %s; pay it before prepaying",
+				apperrors.ErrConflict, next.WeekNumber, l.ID, next.DueDate.Format("2006-01-02"))
+		}
+	}
+
+	var outstanding Money
+	for week := paidWeeks + 1; week <= l.TermWeeks; week++ {
+		outstanding += l.principalOf(week)
+	}
+	outstanding = roundTo(outstanding, 2)
+	principal := roundTo(outstanding-amount, 2)
+	if principal < 0.01 {
+		return nil, fmt.Errorf("%w: a prepayment must be less than the %.2f principal outstanding", apperrors.ErrInvalidArgument, outstanding)
+	}
+
+	weeks := l.TermWeeks - paidWeeks
+	weeklyPrincipal := principal / float64(weeks)
+	if option == PrepaymentReduceTerm {
+		weeklyPrincipal = l.regularPrincipal()
+		weeks = min(weeks, int(math.Ceil(principal/weeklyPrincipal-1e-9)))
+	}
+
+	reamortized := *l
+	reamortized.Prepayments = append(append([]Prepayment{}, l.Prepayments...), Prepayment{
+		LoanID:                      l.ID,
+		Option:                      option,
+		Amount:                      amount,
+		AfterWeek:                   paidWeeks,
+		Principal:                   principal,
+		WeeklyPrincipal:             weeklyPrincipal,
+		PreviousTermWeeks:           l.TermWeeks,
+		PreviousWeeklyPaymentAmount: l.WeeklyPaymentAmount,
+		PreviousTotalLoanAmount:     l.TotalLoanAmount,
+	})
+	reamortized.TermWeeks = paidWeeks + weeks
+	reamortized.WeeklyPaymentAmount = reamortized.weeklyPaymentAt(l.InterestRate)
+	reamortized.TotalLoanAmount = reamortized.totalFromTerms()
+
+	expected, err := reamortized.GenerateSchedule()
+	if err != nil {
+		return nil, err
+	}
+	prepayment := &reamortized.Prepayments[len(reamortized.Prepayments)-1]
+	prepayment.TermWeeks = reamortized.TermWeeks
+	prepayment.WeeklyPaymentAmount = reamortized.WeeklyPaymentAmount
+	prepayment.TotalLoanAmount = reamortized.TotalLoanAmount
+
+	plan := &Reamortization{Prepayment: *prepayment, Changes: []ScheduleChange{}}
+	stored := make(map[int]*ScheduleEntry, len(current))
+	for i := range current {
+		stored[current[i].WeekNumber] = &current[i]
+	}
+	for i := paidWeeks; i < len(expected); i++ {
+		want := expected[i]
+		want.LoanID = l.ID
+		have, ok := stored[want.WeekNumber]
+		delete(stored, want.WeekNumber)
+		switch {
+		case !ok:
+			plan.Changes = append(plan.Changes, ScheduleChange{Kind: ScheduleEntryAdded, WeekNumber: want.WeekNumber, After: &want})
+		case !matchesGenerated(have, &want):
+			if have.hasPayment() {
+				return nil, fmt.Errorf("%w: week %d of loan %d is already paid and cannot be reamortized", apperrors.ErrConflict, have.WeekNumber, l.ID)
+			}
+			after := *have
+			after.DueDate = want.DueDate
+			after.DueAmount = want.DueAmount
+			after.Status = PaymentStatusPending
+			plan.Changes = append(plan.Changes, ScheduleChange{Kind: ScheduleEntryUpdated, WeekNumber: want.WeekNumber, Before: have, After: &after})
+		}
+	}
+	for i := range current {
+		if extra, ok := stored[current[i].WeekNumber]; ok && extra.WeekNumber > reamortized.TermWeeks {
+			if extra.hasPayment() {
+				return nil, fmt.Errorf("%w: week %d of loan %d is paid but beyond the reamortized term", apperrors.ErrConflict, extra.WeekNumber, l.ID)
+			}
+			plan.Changes = append(plan.Changes, ScheduleChange{Kind: ScheduleEntryRemoved, WeekNumber: extra.WeekNumber, Before: extra})
+		}
+	}
+	return plan, nil
+}
@@ -0,0 +1,163 @@
+package loan
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanPrepayment(t *testing.T) {
+	start := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	paidAt := time.Date(2025, 1, 13, 10, 0, 0, 0, time.UTC)
+	today := time.Date(2025, 1, 14, 9, 0, 0, 0, time.UTC)
+
+	// newStored returns a three week loan of 110 a week, due on 13, 20 and 27
+	// January, with the first week paid.
+	newStored := func(t *testing.T) (*Loan, []ScheduleEntry) {
+		l, err := NewLoan(300, 3, 0.1, start)
+		require.NoError(t, err)
+		l.ID = 7
+		schedule, err := l.GenerateSchedule()
+		require.NoError(t, err)
+		for i := range schedule {
+			schedule[i].ID = int64(i + 1)
+			schedule[i].LoanID = l.ID
+		}
+		schedule[0].Status = PaymentStatusPaid
+		schedule[0].PaidAmount = 110
+		schedule[0].PaymentDate = &paidAt
+		return l, schedule
+	}
+	// applied returns l and stored as they are once plan is written.
+	applied := func(l *Loan, stored []ScheduleEntry, plan *Reamortization) (*Loan, []ScheduleEntry) {
+		after := *l
+		after.Prepayments = append(append([]Prepayment{}, l.Prepayments...), plan.Prepayment)
+		after.TermWeeks = plan.Prepayment.TermWeeks
+		after.WeeklyPaymentAmount = plan.Prepayment.WeeklyPaymentAmount
+		after.TotalLoanAmount = plan.Prepayment.TotalLoanAmount
+		schedule := append([]ScheduleEntry{}, stored...)
+		for _, change := range plan.Changes {
+			switch change.Kind {
+			case ScheduleEntryUpdated:
+				schedule[change.WeekNumber-1] = *change.After
+			case ScheduleEntryRemoved:
+				schedule = schedule[:change.WeekNumber-1]
+			}
+		}
+		return &after, schedule
+	}
+
+	t.Run("reducing the installment keeps the term", func(t *testing.T) {
+		l, stored := newStored(t)
+
+		plan, err := PlanPrepayment(l, stored, 50, PrepaymentReduceInstallment, today)
+
+		require.NoError(t, err)
+		p := plan.Prepayment
+		assert.Equal(t, 1, p.AfterWeek)
+		assert.Equal(t, 150.0, p.Principal)
+		assert.Equal(t, 3, p.TermWeeks)
+		assert.Equal(t, 82.5, p.WeeklyPaymentAmount)
+		assert.Equal(t, 325.0, p.TotalLoanAmount, "10 interest on the first week and 7.50 on each of the others")
+		assert.Equal(t, 330.0, p.PreviousTotalLoanAmount)
+		require.Len(t, plan.Changes, 2)
+		for i, change := range plan.Changes {
+			assert.Equal(t, ScheduleEntryUpdated, change.Kind)
+			assert.Equal(t, i+2, change.WeekNumber)
+			assert.Equal(t, 82.5, change.After.DueAmount)
+		}
+
+		reamortized, schedule := applied(l, stored, plan)
+		require.NoError(t, CheckScheduleInvariants(reamortized, schedule))
+		rebuild, err := PlanScheduleRebuild(reamortized, schedule)
+		require.NoError(t, err)
+		assert.Empty(t, rebuild.Changes, "a rebuild keeps the reamortized schedule")
+	})
+
+	t.Run("reducing the term keeps the installment", func(t *testing.T) {
+		l, stored := newStored(t)
+
+		plan, err := PlanPrepayment(l, stored, 100, PrepaymentReduceTerm, today)
+
+		require.NoError(t, err)
+		p := plan.Prepayment
+		assert.Equal(t, 2, p.TermWeeks)
+		assert.Equal(t, 110.0, p.WeeklyPaymentAmount)
+		assert.Equal(t, 320.0, p.TotalLoanAmount)
+		require.Len(t, plan.Changes, 1)
+		assert.Equal(t, ScheduleEntryRemoved, plan.Changes[0].Kind)
+		assert.Equal(t, 3, plan.Changes[0].WeekNumber)
+
+		reamortized, schedule := applied(l, stored, plan)
+		require.NoError(t, CheckScheduleInvariants(reamortized, schedule))
+		rebuild, err := PlanScheduleRebuild(reamortized, schedule)
+		require.NoError(t, err)
+		assert.Empty(t, rebuild.Changes)
+	})
+
+	t.Run("a partial week of principal shortens the last installment", func(t *testing.T) {
+		l, stored := newStored(t)
+
+		plan, err := PlanPrepayment(l, stored, 50, PrepaymentReduceTerm, today)
+
+		require.NoError(t, err)
+		assert.Equal(t, 3, plan.Prepayment.TermWeeks)
+		require.Len(t, plan.Changes, 1)
+		assert.Equal(t, 3, plan.Changes[0].WeekNumber)
+		assert.Equal(t, 55.0, plan.Changes[0].After.DueAmount)
+	})
+
+	t.Run("builds on an earlier prepayment", func(t *testing.T) {
+		l, stored := newStored(t)
+		first, err := PlanPrepayment(l, stored, 50, PrepaymentReduceInstallment, today)
+		require.NoError(t, err)
+		l, stored = applied(l, stored, first)
+
+		plan, err := PlanPrepayment(l, stored, 75, PrepaymentReduceTerm, today)
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, plan.Prepayment.TermWeeks)
+		assert.Equal(t, 75.0, plan.Prepayment.Principal)
+		assert.Equal(t, 317.5, plan.Prepayment.TotalLoanAmount)
+		reamortized, schedule := applied(l, stored, plan)
+		assert.NoError(t, CheckScheduleInvariants(reamortized, schedule))
+	})
+
+	t.Run("rejects a lump sum that settles the loan", func(t *testing.T) {
+		l, stored := newStored(t)
+
+		_, err := PlanPrepayment(l, stored, 200, PrepaymentReduceTerm, today)
+
+		assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+	})
+
+	t.Run("rejects a loan with an overdue installment", func(t *testing.T) {
+		l, stored := newStored(t)
+
+		_, err := PlanPrepayment(l, stored, 50, PrepaymentReduceTerm, time.Date(2025, 1, 21, 0, 0, 0, 0, time.UTC))
+
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+	})
+
+	t.Run("rejects delinquent and paid-off loans", func(t *testing.T) {
+		l, stored := newStored(t)
+		for _, status := range []LoanStatus{StatusDelinquent, StatusPaidOff} {
+			l.Status = status
+			_, err := PlanPrepayment(l, stored, 50, PrepaymentReduceTerm, today)
+			assert.ErrorIs(t, err, apperrors.ErrConflict, status)
+		}
+	})
+
+	t.Run("validates the request", func(t *testing.T) {
+		l, stored := newStored(t)
+
+		_, err := PlanPrepayment(l, stored, 0.001, PrepaymentReduceTerm, today)
+		assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+
+		_, err = PlanPrepayment(l, stored, 50, "SKIP", today)
+		assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+	})
+}
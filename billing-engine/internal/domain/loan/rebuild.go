@@ -0,0 +1,114 @@
+package loan
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"fmt"
+	"math"
+)
+
+type ScheduleChangeKind string
+
+const (
+	ScheduleEntryAdded   ScheduleChangeKind = "ADDED"
+	ScheduleEntryUpdated ScheduleChangeKind = "UPDATED"
+	ScheduleEntryRemoved ScheduleChangeKind = "REMOVED"
+)
+
+// ScheduleChange is one week a rebuild adds, corrects or drops. Before is the
+// stored entry and is nil for an added week; After is the entry as rebuilt
+// and is nil for a removed one.
+type ScheduleChange struct {
+	Kind       ScheduleChangeKind
+	WeekNumber int
+	Before     *ScheduleEntry
+	After      *ScheduleEntry
+}
+
+// ScheduleRebuild describes a schedule regenerated from the loan's terms.
+// Changes is sorted by week and empty when the schedule already matches.
+// Status is the loan status once the changes are applied.
+type ScheduleRebuild struct {
+	LoanID  int64
+	DryRun  bool
+	Changes []ScheduleChange
+	// PaidKept counts the entries left as they are because they carry a
+	// payment.
+	PaidKept int
+	Status   LoanStatus
+}
+
+// hasPayment tells whether entry is paid history, which a rebuild never
+// touches.
+func (entry *ScheduleEntry) hasPayment() bool {
+	return entry.Status == PaymentStatusPaid || entry.PaidAmount > 0 || entry.PaymentDate != nil
+}
+
+// PlanScheduleRebuild compares current, the stored schedule, with the one
+// l's terms produce. Unpaid entries whose due date, amount or status differ
+// are reset to the generated week, missing weeks are added and unpaid weeks
+// past the term are dropped. A paid week past the term cannot be reconciled
+// and is ErrConflict.
+func PlanScheduleRebuild(l *Loan, current []ScheduleEntry) (*ScheduleRebuild, error) {
+	expected, err := l.GenerateSchedule()
+	if err != nil {
+		return nil, err
+	}
+
+	stored := make(map[int]*ScheduleEntry, len(current))
+	for i := range current {
+		stored[current[i].WeekNumber] = &current[i]
+	}
+
+	plan := &ScheduleRebuild{LoanID: l.ID, Changes: []ScheduleChange{}}
+	fullyPaid := true
+	for i := range expected {
+		want := expected[i]
+		want.LoanID = l.ID
+		have, ok := stored[want.WeekNumber]
+		delete(stored, want.WeekNumber)
+
+		switch {
+		case !ok:
+			plan.Changes = append(plan.Changes, ScheduleChange{Kind: ScheduleEntryAdded, WeekNumber: want.WeekNumber, After: &want})
+			fullyPaid = false
+		case have.hasPayment():
+			plan.PaidKept++
+			fullyPaid = fullyPaid && have.Status == PaymentStatusPaid
+		case !matchesGenerated(have, &want):
+			after := *have
+			after.DueDate = want.DueDate
+			after.DueAmount = want.DueAmount
+			after.Status = PaymentStatusPending
+			plan.Changes = append(plan.Changes, ScheduleChange{Kind: ScheduleEntryUpdated, WeekNumber: want.WeekNumber, Before: have, After: &after})
+			fullyPaid = false
+		default:
+			fullyPaid = false
+		}
+	}
+
+	for i := range current {
+		extra := &current[i]
+		if _, ok := stored[extra.WeekNumber]; !ok {
+			continue
+		}
+		if extra.hasPayment() {
+			return nil, fmt.Errorf("%w: week %d of loan %d is paid but beyond its %d week term", apperrors.ErrConflict, extra.WeekNumber, l.ID, l.TermWeeks)
+		}
+		plan.Changes = append(plan.Changes, ScheduleChange{Kind: ScheduleEntryRemoved, WeekNumber: extra.WeekNumber, Before: extra})
+	}
+
+	plan.Status = l.Status
+	switch {
+	case fullyPaid:
+		plan.Status = StatusPaidOff
+	case l.Status == StatusPaidOff:
+		plan.Status = StatusActive
+	}
+	return plan, nil
+}
+
+func matchesGenerated(have, want *ScheduleEntry) bool {
+	return have.Status == PaymentStatusPending &&
+		truncateToDate(have.DueDate).Equal(truncateToDate(want.DueDate)) &&
+		math.Abs(have.DueAmount-want.DueAmount) <= centTolerance
+}
@@ -0,0 +1,130 @@
+package loan
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanScheduleRebuild(t *testing.T) {
+	start := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	paidAt := time.Date(2025, 1, 13, 10, 0, 0, 0, time.UTC)
+
+	// newStored returns a three week loan of 110 a week and the schedule it
+	// was booked with, ids 1 to 3, with the first week paid.
+	newStored := func(t *testing.T) (*Loan, []ScheduleEntry) {
+		l, err := NewLoan(300, 3, 0.1, start)
+		require.NoError(t, err)
+		l.ID = 7
+		schedule, err := l.GenerateSchedule()
+		require.NoError(t, err)
+		for i := range schedule {
+			schedule[i].ID = int64(i + 1)
+			schedule[i].LoanID = l.ID
+		}
+		schedule[0].Status = PaymentStatusPaid
+		schedule[0].PaidAmount = 110
+		schedule[0].PaymentDate = &paidAt
+		return l, schedule
+	}
+
+	t.Run("finds nothing to do on a schedule that matches the terms", func(t *testing.T) {
+		l, stored := newStored(t)
+
+		plan, err := PlanScheduleRebuild(l, stored)
+
+		require.NoError(t, err)
+		assert.Empty(t, plan.Changes)
+		assert.Equal(t, 1, plan.PaidKept)
+		assert.Equal(t, StatusActive, plan.Status)
+	})
+
+	t.Run("resets drifted unpaid weeks and adds missing ones", func(t *testing.T) {
+		l, stored := newStored(t)
+		stored[1].DueAmount = 99
+		stored[1].DueDate = stored[1].DueDate.AddDate(0, 0, 2)
+		stored[1].Status = PaymentStatusMissed
+		stored = stored[:2]
+
+		plan, err := PlanScheduleRebuild(l, stored)
+
+		require.NoError(t, err)
+		require.Len(t, plan.Changes, 2)
+		updated := plan.Changes[0]
+		assert.Equal(t, ScheduleEntryUpdated, updated.Kind)
+		assert.Equal(t, 2, updated.WeekNumber)
+		assert.Equal(t, 99.0, updated.Before.DueAmount, "before is the stored entry")
+		assert.Equal(t, int64(2), updated.After.ID)
+		assert.Equal(t, 110.0, updated.After.DueAmount)
+		assert.Equal(t, start.AddDate(0, 0, 14), updated.After.DueDate)
+		assert.Equal(t, PaymentStatusPending, updated.After.Status)
+
+		added := plan.Changes[1]
+		assert.Equal(t, ScheduleEntryAdded, added.Kind)
+		assert.Nil(t, added.Before)
+		assert.Equal(t, 3, added.After.WeekNumber)
+		assert.Equal(t, l.ID, added.After.LoanID)
+		assert.Equal(t, 110.0, added.After.DueAmount)
+	})
+
+	t.Run("keeps paid weeks even when they differ from the terms", func(t *testing.T) {
+		l, stored := newStored(t)
+		stored[0].DueAmount = 105
+
+		plan, err := PlanScheduleRebuild(l, stored)
+
+		require.NoError(t, err)
+		assert.Empty(t, plan.Changes)
+		assert.Equal(t, 1, plan.PaidKept)
+	})
+
+	t.Run("removes unpaid weeks past the term", func(t *testing.T) {
+		l, stored := newStored(t)
+		extra := stored[2]
+		extra.ID, extra.WeekNumber = 4, 4
+		stored = append(stored, extra)
+
+		plan, err := PlanScheduleRebuild(l, stored)
+
+		require.NoError(t, err)
+		require.Len(t, plan.Changes, 1)
+		assert.Equal(t, ScheduleEntryRemoved, plan.Changes[0].Kind)
+		assert.Equal(t, int64(4), plan.Changes[0].Before.ID)
+		assert.Nil(t, plan.Changes[0].After)
+	})
+
+	t.Run("refuses to drop a paid week past the term", func(t *testing.T) {
+		l, stored := newStored(t)
+		extra := stored[0]
+		extra.ID, extra.WeekNumber = 4, 4
+		stored = append(stored, extra)
+
+		_, err := PlanScheduleRebuild(l, stored)
+
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+	})
+
+	t.Run("marks the loan paid off when only paid weeks remain", func(t *testing.T) {
+		l, stored := newStored(t)
+		l.TermWeeks, l.TotalLoanAmount = 1, 110
+
+		plan, err := PlanScheduleRebuild(l, stored[:1])
+
+		require.NoError(t, err)
+		assert.Equal(t, StatusPaidOff, plan.Status)
+	})
+
+	t.Run("reopens a paid-off loan that gains unpaid weeks", func(t *testing.T) {
+		l, stored := newStored(t)
+		l.Status = StatusPaidOff
+
+		plan, err := PlanScheduleRebuild(l, stored[:1])
+
+		require.NoError(t, err)
+		assert.Len(t, plan.Changes, 2)
+		assert.Equal(t, StatusActive, plan.Status)
+	})
+}
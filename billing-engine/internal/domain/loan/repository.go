@@ -2,36 +2,212 @@ package loan
 
 import (
 	"context"
+	"time"
 
-	"github.com/jackc/pgx/v5"
+	"github.com/google/uuid"
 )
 
-type Repository interface {
-	CreateLoan(ctx context.Context, customerID int64, loan *Loan, schedule []ScheduleEntry) (createdLoan *Loan, err error)
+// TxRepository is the view of the repository inside a transaction opened by
+// Repository.WithinTransaction. Its writes become visible when the
+// transaction commits.
+type TxRepository interface {
+	// LockLoanForPayment serializes payments on the loan until the
+	// transaction ends. It does not wait: when another transaction holds the
+	// lock it returns ErrConflict.
+	LockLoanForPayment(ctx context.Context, loanID int64) error
 
-	GetLoanByID(ctx context.Context, loanID int64) (*Loan, error)
+	// GetActiveHold returns the loan's open hold, or nil when there is none.
+	GetActiveHold(ctx context.Context, loanID int64) (*Hold, error)
 
-	GetScheduleByLoanID(ctx context.Context, loanID int64) ([]ScheduleEntry, error)
+	FindOldestUnpaidEntryForUpdate(ctx context.Context, loanID int64) (*ScheduleEntry, error)
 
-	GetUnpaidSchedules(ctx context.Context, loanID int64) ([]ScheduleEntry, error)
+	// FindOldestOpenFeeForUpdate returns the loan's oldest fee that is
+	// neither paid nor waived, with its tax line, and keeps it from changing
+	// until the transaction ends. Without one it is ErrNotFound.
+	FindOldestOpenFeeForUpdate(ctx context.Context, loanID int64) (*Fee, error)
 
-	GetLastTwoDueUnpaidSchedules(ctx context.Context, loanID int64) ([]ScheduleEntry, error)
+	// SettleFee stores the fee's PaidAt.
+	SettleFee(ctx context.Context, fee *Fee) error
+
+	UpdateScheduleEntry(ctx context.Context, entry *ScheduleEntry) error
+
+	// GetScheduleForUpdate returns the loan's schedule ordered by week and
+	// keeps it from changing until the transaction ends.
+	GetScheduleForUpdate(ctx context.Context, loanID int64) ([]ScheduleEntry, error)
+
+	// ApplyScheduleChanges writes a rebuild: added weeks are inserted and get
+	// their ID and timestamps, updated weeks take their new due date, amount
+	// and status, and removed weeks are deleted together with what references
+	// them.
+	ApplyScheduleChanges(ctx context.Context, loanID int64, changes []ScheduleChange) error
+
+	// RecordPayment adds the payment to the ledger and sets its ID and
+	// CreatedAt. A reference already recorded for the channel is
+	// ErrAlreadyExists.
+	RecordPayment(ctx context.Context, payment *Payment) error
+
+	UpdateLoanStatus(ctx context.Context, loanID int64, status LoanStatus) error
+
+	// GetRateHistory returns the loan's rate changes, oldest first.
+	GetRateHistory(ctx context.Context, loanID int64) ([]RateChange, error)
+
+	// RepriceLoan stores the repricing's rate and totals on the loan and adds
+	// its change to the rate history, setting the change's ID.
+	RepriceLoan(ctx context.Context, repricing *Repricing) error
+
+	// GetScheduleAdjustments returns the loan's adjustments, removed ones
+	// included, in the order they were applied.
+	GetScheduleAdjustments(ctx context.Context, loanID int64) ([]ScheduleAdjustment, error)
+
+	// AddScheduleAdjustment stores the plan's adjustment, setting its ID, and
+	// the plan's total on the loan.
+	AddScheduleAdjustment(ctx context.Context, plan *AdjustmentPlan) error
 
-	FindOldestUnpaidEntryForUpdate(ctx context.Context, tx pgx.Tx, loanID int64) (*ScheduleEntry, error)
+	// RemoveScheduleAdjustment marks the plan's adjustment removed with its
+	// RemovedBy and RemovedAt and stores the plan's total on the loan. An
+	// adjustment that is already removed is ErrNotFound.
+	RemoveScheduleAdjustment(ctx context.Context, plan *AdjustmentPlan) error
 
-	UpdateScheduleEntryInTx(ctx context.Context, tx pgx.Tx, entry *ScheduleEntry) error
+	// GetPrepayments returns the loan's prepayments, oldest first.
+	GetPrepayments(ctx context.Context, loanID int64) ([]Prepayment, error)
 
-	UpdateLoanStatusInTx(ctx context.Context, tx pgx.Tx, loanID int64, status LoanStatus) error
+	// RecordPrepayment stores the reamortization's terms on the loan and adds
+	// its prepayment, setting the prepayment's ID. A reference already
+	// recorded for the channel is ErrAlreadyExists.
+	RecordPrepayment(ctx context.Context, reamortization *Reamortization) error
 
-	CheckIfAllPaymentsMadeInTx(ctx context.Context, tx pgx.Tx, loanID int64) (bool, error)
+	// CheckIfAllPaymentsMade reports whether every installment of the loan
+	// is paid and every fee is paid or waived.
+	CheckIfAllPaymentsMade(ctx context.Context, loanID int64) (bool, error)
+}
+
+type Repository interface {
+	// CreateLoan stores the loan and its schedule and links the loan to the
+	// customer in one transaction. It returns ErrConflict, keeping nothing,
+	// when the customer does not exist, is inactive or has a loan that is not
+	// paid off, and ErrAlreadyExists when the public ID or external reference
+	// is taken.
+	CreateLoan(ctx context.Context, customerID int64, loan *Loan, schedule []ScheduleEntry) (createdLoan *Loan, err error)
 
-	GetTotalOutstandingAmount(ctx context.Context, loanID int64) (float64, error)
+	GetLoanByID(ctx context.Context, loanID int64) (*Loan, error)
+
+	// GetLoanWithSchedule returns the loan with its Schedule filled in, read
+	// in a single round trip.
+	GetLoanWithSchedule(ctx context.Context, loanID int64) (*Loan, error)
 
-	BeginTx(ctx context.Context) (pgx.Tx, error)
+	GetLoanByPublicID(ctx context.Context, publicID uuid.UUID) (*Loan, error)
 
-	CommitTx(ctx context.Context, tx pgx.Tx) error
+	GetLoanByExternalRef(ctx context.Context, externalRef string) (*Loan, error)
+
+	GetScheduleByLoanID(ctx context.Context, loanID int64) ([]ScheduleEntry, error)
 
-	RollbackTx(ctx context.Context, tx pgx.Tx) error
+	GetUnpaidSchedules(ctx context.Context, loanID int64) ([]ScheduleEntry, error)
+
+	GetLastTwoDueUnpaidSchedules(ctx context.Context, loanID int64) ([]ScheduleEntry, error)
+
+	// WithinTransaction runs fn in one transaction, committing when fn
+	// returns nil and rolling back when it returns an error or panics. fn's
+	// error is returned unchanged; failing to begin or commit is
+	// ErrDatabase.
+	WithinTransaction(ctx context.Context, fn func(tx TxRepository) error) error
+
+	// PlaceHold stores hold as the loan's open hold and sets its ID. A loan
+	// that already has an open hold is ErrConflict, an unknown loan
+	// ErrNotFound. It waits for a payment in progress on the loan to finish.
+	PlaceHold(ctx context.Context, hold *Hold) error
+
+	// ReleaseHold closes the loan's open hold and returns it. Without an open
+	// hold it is ErrNotFound.
+	ReleaseHold(ctx context.Context, loanID int64, releasedBy *string, releasedAt time.Time) (*Hold, error)
+
+	// GetActiveHold returns the loan's open hold, or nil when there is none.
+	GetActiveHold(ctx context.Context, loanID int64) (*Hold, error)
+
+	// GetRateHistory returns the loan's rate changes, oldest first. The
+	// slice is empty for a loan that was never repriced.
+	GetRateHistory(ctx context.Context, loanID int64) ([]RateChange, error)
+
+	// GetScheduleAdjustments returns the loan's adjustments, removed ones
+	// included, in the order they were applied.
+	GetScheduleAdjustments(ctx context.Context, loanID int64) ([]ScheduleAdjustment, error)
+
+	// GetPrepayments returns the loan's prepayments, oldest first. The slice
+	// is empty for a loan that was never prepaid.
+	GetPrepayments(ctx context.Context, loanID int64) ([]Prepayment, error)
+
+	// PostFee stores the fee and its tax line, if it has one, together and
+	// sets their IDs. An unknown loan is ErrNotFound.
+	PostFee(ctx context.Context, fee *Fee) error
+
+	// GetFees returns the loan's fees, paid ones included, each with its tax
+	// line and waivers, oldest first.
+	GetFees(ctx context.Context, loanID int64) ([]Fee, error)
+
+	// RequestFeeWaiver stores waiver as pending and sets its ID. A fee that
+	// is not the loan's is ErrNotFound, one that already has a pending or
+	// approved waiver ErrConflict.
+	RequestFeeWaiver(ctx context.Context, waiver *FeeWaiver) error
+
+	// DecideFeeWaiver stores the waiver's Status, DecidedBy and DecidedAt.
+	// A waiver that is no longer pending is ErrConflict.
+	DecideFeeWaiver(ctx context.Context, waiver *FeeWaiver) error
+
+	// ListBalanceItems returns the charges and holdings recorded against
+	// the loan outside its schedule, for the outstanding breakdown: unapplied
+	// direct-debit collections and the fees that are neither paid nor
+	// waived, each followed by its tax.
+	ListBalanceItems(ctx context.Context, loanID int64) ([]BalanceItem, error)
+
+	GetLastModified(ctx context.Context, loanID int64) (time.Time, error)
+
+	// SumPaymentsByChannel totals the payments and prepayments with
+	// from <= paid_at < to by channel, archived loans included. Channels
+	// without payments have no row.
+	SumPaymentsByChannel(ctx context.Context, from, to time.Time) ([]ChannelCollections, error)
+
+	// ListPayments returns the loan's latest payments from the ledger,
+	// newest first, at most limit of them.
+	ListPayments(ctx context.Context, loanID int64, limit int) ([]Payment, error)
+
+	// SearchLoans returns at most q.Limit loans matching q. A search by
+	// payment returns matches newest payment first, a search by name alone
+	// in customer name order.
+	SearchLoans(ctx context.Context, q LoanSearch) ([]SearchMatch, error)
+
+	// SumTaxLines totals the tax lines with from <= created_at < to by
+	// jurisdiction and fee type, in that order, archived loans included.
+	SumTaxLines(ctx context.Context, from, to time.Time) ([]TaxTotals, error)
+
+	// GetActiveLoanTenants returns the tenant of every active loan, keyed
+	// by loan ID, so that a batch job can apply each tenant's settings.
+	GetActiveLoanTenants(ctx context.Context) (map[int64]string, error)
+
+	// UpdateDaysPastDue stores the loan's days past due. The row, and so its
+	// updated_at, is left alone when the value has not changed.
+	UpdateDaysPastDue(ctx context.Context, loanID int64, daysPastDue int) error
+
+	// StreamLoans calls fn for every loan matching filter in ID order, as the
+	// rows arrive from the database. An error from fn stops the query and is
+	// returned unchanged.
+	StreamLoans(ctx context.Context, filter LoanFilter, fn func(*Loan) error) error
+}
+
+// LoanFilter selects the loans StreamLoans returns. The zero value matches
+// every loan.
+type LoanFilter struct {
+	// AfterID is the cursor: only loans with a higher ID are returned.
+	AfterID int64
+	// MinDaysPastDue keeps loans at least this many days past due.
+	MinDaysPastDue int
+	// Status keeps loans in that status; empty matches every status.
+	Status LoanStatus
+}
 
-	GetAllActiveLoanIDs(ctx context.Context) ([]int64, error)
+// PartitionRepository keeps partitioned storage ahead of the data: it
+// creates the partitions the next loans' schedules will go to and those of
+// the payments made from now through monthsAhead months later. It returns
+// the names of the partitions it created. Backends without partitioned
+// tables create none.
+type PartitionRepository interface {
+	EnsurePartitions(ctx context.Context, now time.Time, monthsAhead int) (created []string, err error)
 }
@@ -1,10 +1,12 @@
 package loan
 
 import (
+	"billing-engine/internal/pkg/apperrors"
 	"context"
 	"testing"
+	"time"
 
-	"github.com/jackc/pgx/v5"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
@@ -13,12 +15,12 @@ type MockRepository struct {
 	mock.Mock
 }
 
-type TxMock struct {
-	pgx.Tx
+// MockTxRepository is the TxRepository a MockRepository.WithinTransaction
+// expectation hands to the callback.
+type MockTxRepository struct {
+	mock.Mock
 }
 
-var tx pgx.Tx = &TxMock{}
-
 func (m *MockRepository) CreateLoan(ctx context.Context, customerId int64, loan *Loan, schedule []ScheduleEntry) (*Loan, error) {
 	args := m.Called(ctx, customerId, loan, schedule)
 	return args.Get(0).(*Loan), args.Error(1)
@@ -29,6 +31,21 @@ func (m *MockRepository) GetLoanByID(ctx context.Context, loanID int64) (*Loan,
 	return args.Get(0).(*Loan), args.Error(1)
 }
 
+func (m *MockRepository) GetLoanWithSchedule(ctx context.Context, loanID int64) (*Loan, error) {
+	args := m.Called(ctx, loanID)
+	return args.Get(0).(*Loan), args.Error(1)
+}
+
+func (m *MockRepository) GetLoanByExternalRef(ctx context.Context, externalRef string) (*Loan, error) {
+	args := m.Called(ctx, externalRef)
+	return args.Get(0).(*Loan), args.Error(1)
+}
+
+func (m *MockRepository) GetLoanByPublicID(ctx context.Context, publicID uuid.UUID) (*Loan, error) {
+	args := m.Called(ctx, publicID)
+	return args.Get(0).(*Loan), args.Error(1)
+}
+
 func (m *MockRepository) GetScheduleByLoanID(ctx context.Context, loanID int64) ([]ScheduleEntry, error) {
 	args := m.Called(ctx, loanID)
 	return args.Get(0).([]ScheduleEntry), args.Error(1)
@@ -44,49 +61,228 @@ func (m *MockRepository) GetLastTwoDueUnpaidSchedules(ctx context.Context, loanI
 	return args.Get(0).([]ScheduleEntry), args.Error(1)
 }
 
-func (m *MockRepository) FindOldestUnpaidEntryForUpdate(ctx context.Context, tx pgx.Tx, loanID int64) (*ScheduleEntry, error) {
-	args := m.Called(ctx, tx, loanID)
+func (m *MockRepository) PlaceHold(ctx context.Context, hold *Hold) error {
+	args := m.Called(ctx, hold)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ReleaseHold(ctx context.Context, loanID int64, releasedBy *string, releasedAt time.Time) (*Hold, error) {
+	args := m.Called(ctx, loanID, releasedBy, releasedAt)
+	hold, _ := args.Get(0).(*Hold)
+	return hold, args.Error(1)
+}
+
+func (m *MockRepository) GetActiveHold(ctx context.Context, loanID int64) (*Hold, error) {
+	args := m.Called(ctx, loanID)
+	hold, _ := args.Get(0).(*Hold)
+	return hold, args.Error(1)
+}
+
+func (m *MockRepository) ListBalanceItems(ctx context.Context, loanID int64) ([]BalanceItem, error) {
+	args := m.Called(ctx, loanID)
+	items, _ := args.Get(0).([]BalanceItem)
+	return items, args.Error(1)
+}
+
+func (m *MockRepository) GetLastModified(ctx context.Context, loanID int64) (time.Time, error) {
+	args := m.Called(ctx, loanID)
+	return args.Get(0).(time.Time), args.Error(1)
+}
+
+func (m *MockRepository) SumPaymentsByChannel(ctx context.Context, from, to time.Time) ([]ChannelCollections, error) {
+	args := m.Called(ctx, from, to)
+	totals, _ := args.Get(0).([]ChannelCollections)
+	return totals, args.Error(1)
+}
+
+func (m *MockRepository) ListPayments(ctx context.Context, loanID int64, limit int) ([]Payment, error) {
+	args := m.Called(ctx, loanID, limit)
+	payments, _ := args.Get(0).([]Payment)
+	return payments, args.Error(1)
+}
+
+func (m *MockRepository) SearchLoans(ctx context.Context, q LoanSearch) ([]SearchMatch, error) {
+	args := m.Called(ctx, q)
+	matches, _ := args.Get(0).([]SearchMatch)
+	return matches, args.Error(1)
+}
+
+func (m *MockRepository) SumTaxLines(ctx context.Context, from, to time.Time) ([]TaxTotals, error) {
+	args := m.Called(ctx, from, to)
+	totals, _ := args.Get(0).([]TaxTotals)
+	return totals, args.Error(1)
+}
+
+func (m *MockRepository) StreamLoans(ctx context.Context, filter LoanFilter, fn func(*Loan) error) error {
+	args := m.Called(ctx, filter)
+	loans, _ := args.Get(0).([]*Loan)
+	for _, l := range loans {
+		if err := fn(l); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+// WithinTransaction runs fn against the TxRepository the expectation returns
+// and then returns the expectation's error, which stands in for a failed
+// commit. When the expectation returns no TxRepository fn is not run, as when
+// the transaction cannot begin.
+func (m *MockRepository) GetRateHistory(ctx context.Context, loanID int64) ([]RateChange, error) {
+	args := m.Called(ctx, loanID)
+	history, _ := args.Get(0).([]RateChange)
+	return history, args.Error(1)
+}
+
+func (m *MockRepository) GetScheduleAdjustments(ctx context.Context, loanID int64) ([]ScheduleAdjustment, error) {
+	args := m.Called(ctx, loanID)
+	adjustments, _ := args.Get(0).([]ScheduleAdjustment)
+	return adjustments, args.Error(1)
+}
+
+func (m *MockRepository) GetPrepayments(ctx context.Context, loanID int64) ([]Prepayment, error) {
+	args := m.Called(ctx, loanID)
+	prepayments, _ := args.Get(0).([]Prepayment)
+	return prepayments, args.Error(1)
+}
+
+func (m *MockRepository) PostFee(ctx context.Context, fee *Fee) error {
+	args := m.Called(ctx, fee)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetFees(ctx context.Context, loanID int64) ([]Fee, error) {
+	args := m.Called(ctx, loanID)
+	fees, _ := args.Get(0).([]Fee)
+	return fees, args.Error(1)
+}
+
+func (m *MockRepository) RequestFeeWaiver(ctx context.Context, waiver *FeeWaiver) error {
+	args := m.Called(ctx, waiver)
+	return args.Error(0)
+}
+
+func (m *MockRepository) DecideFeeWaiver(ctx context.Context, waiver *FeeWaiver) error {
+	args := m.Called(ctx, waiver)
+	return args.Error(0)
+}
+
+func (m *MockRepository) WithinTransaction(ctx context.Context, fn func(tx TxRepository) error) error {
+	args := m.Called(ctx)
+	txRepo, ok := args.Get(0).(TxRepository)
+	if !ok {
+		return args.Error(1)
+	}
+	if err := fn(txRepo); err != nil {
+		return err
+	}
+	return args.Error(1)
+}
+
+func (m *MockTxRepository) LockLoanForPayment(ctx context.Context, loanID int64) error {
+	args := m.Called(ctx, loanID)
+	return args.Error(0)
+}
+
+func (m *MockTxRepository) GetActiveHold(ctx context.Context, loanID int64) (*Hold, error) {
+	args := m.Called(ctx, loanID)
+	hold, _ := args.Get(0).(*Hold)
+	return hold, args.Error(1)
+}
+
+func (m *MockTxRepository) FindOldestUnpaidEntryForUpdate(ctx context.Context, loanID int64) (*ScheduleEntry, error) {
+	args := m.Called(ctx, loanID)
 	return args.Get(0).(*ScheduleEntry), args.Error(1)
 }
 
-func (m *MockRepository) UpdateScheduleEntryInTx(ctx context.Context, tx pgx.Tx, entry *ScheduleEntry) error {
-	args := m.Called(ctx, tx, entry)
+func (m *MockTxRepository) FindOldestOpenFeeForUpdate(ctx context.Context, loanID int64) (*Fee, error) {
+	args := m.Called(ctx, loanID)
+	fee, _ := args.Get(0).(*Fee)
+	return fee, args.Error(1)
+}
+
+func (m *MockTxRepository) SettleFee(ctx context.Context, fee *Fee) error {
+	args := m.Called(ctx, fee)
 	return args.Error(0)
 }
 
-func (m *MockRepository) UpdateLoanStatusInTx(ctx context.Context, tx pgx.Tx, loanID int64, status LoanStatus) error {
-	args := m.Called(ctx, tx, loanID, status)
+func (m *MockTxRepository) UpdateScheduleEntry(ctx context.Context, entry *ScheduleEntry) error {
+	args := m.Called(ctx, entry)
 	return args.Error(0)
 }
 
-func (m *MockRepository) CheckIfAllPaymentsMadeInTx(ctx context.Context, tx pgx.Tx, loanID int64) (bool, error) {
-	args := m.Called(ctx, tx, loanID)
-	return args.Bool(0), args.Error(1)
+func (m *MockTxRepository) GetScheduleForUpdate(ctx context.Context, loanID int64) ([]ScheduleEntry, error) {
+	args := m.Called(ctx, loanID)
+	schedule, _ := args.Get(0).([]ScheduleEntry)
+	return schedule, args.Error(1)
+}
+
+func (m *MockTxRepository) ApplyScheduleChanges(ctx context.Context, loanID int64, changes []ScheduleChange) error {
+	args := m.Called(ctx, loanID, changes)
+	return args.Error(0)
 }
 
-func (m *MockRepository) GetTotalOutstandingAmount(ctx context.Context, loanID int64) (float64, error) {
+func (m *MockTxRepository) GetRateHistory(ctx context.Context, loanID int64) ([]RateChange, error) {
 	args := m.Called(ctx, loanID)
-	return args.Get(0).(float64), args.Error(1)
+	history, _ := args.Get(0).([]RateChange)
+	return history, args.Error(1)
 }
 
-func (m *MockRepository) BeginTx(ctx context.Context) (pgx.Tx, error) {
-	args := m.Called(ctx)
-	return args.Get(0).(pgx.Tx), args.Error(1)
+func (m *MockTxRepository) RepriceLoan(ctx context.Context, repricing *Repricing) error {
+	args := m.Called(ctx, repricing)
+	return args.Error(0)
+}
+
+func (m *MockTxRepository) GetScheduleAdjustments(ctx context.Context, loanID int64) ([]ScheduleAdjustment, error) {
+	args := m.Called(ctx, loanID)
+	adjustments, _ := args.Get(0).([]ScheduleAdjustment)
+	return adjustments, args.Error(1)
 }
 
-func (m *MockRepository) CommitTx(ctx context.Context, tx pgx.Tx) error {
-	args := m.Called(ctx, tx)
+func (m *MockTxRepository) AddScheduleAdjustment(ctx context.Context, plan *AdjustmentPlan) error {
+	args := m.Called(ctx, plan)
 	return args.Error(0)
 }
 
-func (m *MockRepository) RollbackTx(ctx context.Context, tx pgx.Tx) error {
-	args := m.Called(ctx, tx)
+func (m *MockTxRepository) RemoveScheduleAdjustment(ctx context.Context, plan *AdjustmentPlan) error {
+	args := m.Called(ctx, plan)
 	return args.Error(0)
 }
 
-func (m *MockRepository) GetAllActiveLoanIDs(ctx context.Context) ([]int64, error) {
+func (m *MockTxRepository) GetPrepayments(ctx context.Context, loanID int64) ([]Prepayment, error) {
+	args := m.Called(ctx, loanID)
+	prepayments, _ := args.Get(0).([]Prepayment)
+	return prepayments, args.Error(1)
+}
+
+func (m *MockTxRepository) RecordPrepayment(ctx context.Context, reamortization *Reamortization) error {
+	args := m.Called(ctx, reamortization)
+	return args.Error(0)
+}
+
+func (m *MockTxRepository) RecordPayment(ctx context.Context, payment *Payment) error {
+	args := m.Called(ctx, payment)
+	return args.Error(0)
+}
+
+func (m *MockTxRepository) UpdateLoanStatus(ctx context.Context, loanID int64, status LoanStatus) error {
+	args := m.Called(ctx, loanID, status)
+	return args.Error(0)
+}
+
+func (m *MockTxRepository) CheckIfAllPaymentsMade(ctx context.Context, loanID int64) (bool, error) {
+	args := m.Called(ctx, loanID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepository) GetActiveLoanTenants(ctx context.Context) (map[int64]string, error) {
 	args := m.Called(ctx)
-	return args.Get(0).([]int64), args.Error(1)
+	return args.Get(0).(map[int64]string), args.Error(1)
+}
+
+func (m *MockRepository) UpdateDaysPastDue(ctx context.Context, loanID int64, daysPastDue int) error {
+	args := m.Called(ctx, loanID, daysPastDue)
+	return args.Error(0)
 }
 
 func TestRepository_CreateLoan(t *testing.T) {
@@ -166,112 +362,59 @@ func TestRepository_GetLastTwoDueUnpaidSchedules(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
-func TestRepository_FindOldestUnpaidEntryForUpdate(t *testing.T) {
+func TestRepository_ListBalanceItems(t *testing.T) {
 	mockRepo := new(MockRepository)
 	ctx := context.Background()
 	loanID := int64(1)
-	expectedEntry := &ScheduleEntry{}
+	expected := []BalanceItem{{Kind: BalanceSuspense, Amount: 110}}
 
-	mockRepo.On("FindOldestUnpaidEntryForUpdate", ctx, tx, loanID).Return(expectedEntry, nil)
+	mockRepo.On("ListBalanceItems", ctx, loanID).Return(expected, nil)
 
-	result, err := mockRepo.FindOldestUnpaidEntryForUpdate(ctx, tx, loanID)
+	result, err := mockRepo.ListBalanceItems(ctx, loanID)
 	require.NoError(t, err)
-	require.Equal(t, expectedEntry, result)
+	require.Equal(t, expected, result)
 
 	mockRepo.AssertExpectations(t)
 }
 
-func TestRepository_UpdateScheduleEntryInTx(t *testing.T) {
-	mockRepo := new(MockRepository)
+func TestRepository_WithinTransaction(t *testing.T) {
 	ctx := context.Background()
-	entry := &ScheduleEntry{}
-
-	mockRepo.On("UpdateScheduleEntryInTx", ctx, tx, entry).Return(nil)
-
-	err := mockRepo.UpdateScheduleEntryInTx(ctx, tx, entry)
-	require.NoError(t, err)
-
-	mockRepo.AssertExpectations(t)
-}
-
-func TestRepository_UpdateLoanStatusInTx(t *testing.T) {
-	mockRepo := new(MockRepository)
-	ctx := context.Background()
-	loanID := int64(1)
-	status := LoanStatus("PAID")
-
-	mockRepo.On("UpdateLoanStatusInTx", ctx, tx, loanID, status).Return(nil)
-
-	err := mockRepo.UpdateLoanStatusInTx(ctx, tx, loanID, status)
-	require.NoError(t, err)
-
-	mockRepo.AssertExpectations(t)
-}
-
-func TestRepository_CheckIfAllPaymentsMadeInTx(t *testing.T) {
-	mockRepo := new(MockRepository)
-	ctx := context.Background()
-
 	loanID := int64(1)
-	expectedResult := true
-
-	mockRepo.On("CheckIfAllPaymentsMadeInTx", ctx, tx, loanID).Return(expectedResult, nil)
 
-	result, err := mockRepo.CheckIfAllPaymentsMadeInTx(ctx, tx, loanID)
-	require.NoError(t, err)
-	require.Equal(t, expectedResult, result)
-
-	mockRepo.AssertExpectations(t)
-}
-
-func TestRepository_GetTotalOutstandingAmount(t *testing.T) {
-	mockRepo := new(MockRepository)
-	ctx := context.Background()
-	loanID := int64(1)
-	expectedAmount := 1000.0
-
-	mockRepo.On("GetTotalOutstandingAmount", ctx, loanID).Return(expectedAmount, nil)
-
-	result, err := mockRepo.GetTotalOutstandingAmount(ctx, loanID)
-	require.NoError(t, err)
-	require.Equal(t, expectedAmount, result)
-
-	mockRepo.AssertExpectations(t)
-}
-
-func TestRepository_BeginTx(t *testing.T) {
-	mockRepo := new(MockRepository)
-	ctx := context.Background()
-
-	mockRepo.On("BeginTx", ctx).Return(tx, nil)
-
-	result, err := mockRepo.BeginTx(ctx)
-	require.NoError(t, err)
-	require.NotNil(t, result)
-
-	mockRepo.AssertExpectations(t)
-}
-
-func TestRepository_CommitTx(t *testing.T) {
-	mockRepo := new(MockRepository)
-	ctx := context.Background()
-
-	mockRepo.On("CommitTx", ctx, tx).Return(nil)
-
-	err := mockRepo.CommitTx(ctx, tx)
-	require.NoError(t, err)
-
-	mockRepo.AssertExpectations(t)
-}
-
-func TestRepository_RollbackTx(t *testing.T) {
-	mockRepo := new(MockRepository)
-	ctx := context.Background()
-
-	mockRepo.On("RollbackTx", ctx, tx).Return(nil)
-
-	err := mockRepo.RollbackTx(ctx, tx)
-	require.NoError(t, err)
-
-	mockRepo.AssertExpectations(t)
+	t.Run("runs fn and commits", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		txRepo := new(MockTxRepository)
+		mockRepo.On("WithinTransaction", ctx).Return(txRepo, nil)
+		txRepo.On("CheckIfAllPaymentsMade", ctx, loanID).Return(true, nil)
+
+		var allPaid bool
+		err := mockRepo.WithinTransaction(ctx, func(tx TxRepository) (err error) {
+			allPaid, err = tx.CheckIfAllPaymentsMade(ctx, loanID)
+			return err
+		})
+		require.NoError(t, err)
+		require.True(t, allPaid)
+
+		mockRepo.AssertExpectations(t)
+		txRepo.AssertExpectations(t)
+	})
+
+	t.Run("returns the callback's error", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("WithinTransaction", ctx).Return(new(MockTxRepository), nil)
+
+		err := mockRepo.WithinTransaction(ctx, func(TxRepository) error { return apperrors.ErrLoanOnHold })
+		require.ErrorIs(t, err, apperrors.ErrLoanOnHold)
+	})
+
+	t.Run("does not run fn when the transaction cannot begin", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("WithinTransaction", ctx).Return(nil, apperrors.ErrDatabase)
+
+		err := mockRepo.WithinTransaction(ctx, func(TxRepository) error {
+			t.Fatal("fn must not run")
+			return nil
+		})
+		require.ErrorIs(t, err, apperrors.ErrDatabase)
+	})
 }
@@ -0,0 +1,211 @@
+package loan
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// rateTolerance absorbs float noise when comparing rates, which are stored
+// with four decimals.
+const rateTolerance = 0.00005
+
+// RateChange is one repricing of a variable-rate loan: installments due on or
+// after EffectiveFrom are charged Rate instead of PreviousRate. A loan's
+// changes are kept in the order they were made, each taking effect after the
+// one before it.
+type RateChange struct {
+	ID            int64
+	LoanID        int64
+	PreviousRate  float64
+	Rate          float64
+	EffectiveFrom time.Time
+	ChangedBy     *string
+	CreatedAt     time.Time
+}
+
+// Repricing is a rate change together with the installments it recalculates
+// and the loan terms that follow from it.
+type Repricing struct {
+	Change              RateChange
+	Changes             []ScheduleChange
+	WeeklyPaymentAmount Money
+	TotalLoanAmount     Money
+}
+
+// RepricingFilter selects the loans a batch repricing applies to. Paid-off
+// loans are never repriced. CurrentRate, when set, keeps only the loans
+// charged that rate today, which is how a base-rate change reaches the loans
+// booked on it without touching those repriced individually.
+type RepricingFilter struct {
+	CurrentRate *float64
+}
+
+// RepricingSkip is a loan a batch repricing could not reprice, such as one
+// already paid past the effective date or on hold.
+type RepricingSkip struct {
+	LoanID int64
+	Reason string
+}
+
+// RepricingReport sums up a batch repricing.
+type RepricingReport struct {
+	Rate          float64
+	EffectiveFrom time.Time
+	Repriced      int
+	Skipped       []RepricingSkip
+}
+
+// maxInterestRate is the first rate the interest_rate column cannot hold.
+const maxInterestRate = 10
+
+// validateRepricing checks a requested rate change and returns the rate
+// rounded to the four decimals it is stored with.
+func validateRepricing(rate float64, effectiveFrom time.Time) (float64, error) {
+	if rate < 0 || math.IsNaN(rate) {
+		return 0, fmt.Errorf("%w: interest rate must be non-negative", apperrors.ErrInvalidArgument)
+	}
+	if rate = roundTo(rate, 4); rate >= maxInterestRate {
+		return 0, fmt.Errorf("%w: interest rate must be below %d", apperrors.ErrInvalidArgument, maxInterestRate)
+	}
+	if effectiveFrom.IsZero() {
+		return 0, fmt.Errorf("%w: effective date is required", apperrors.ErrInvalidArgument)
+	}
+	return rate, nil
+}
+
+// originalRate is the rate the loan was booked at.
+func (l *Loan) originalRate() float64 {
+	if len(l.RateHistory) > 0 {
+		return l.RateHistory[0].PreviousRate
+	}
+	return l.InterestRate
+}
+
+// rateOn is the rate charged on an installment due on due.
+func (l *Loan) rateOn(due time.Time) float64 {
+	rate := l.originalRate()
+	day := truncateToDate(due)
+	for _, change := range l.RateHistory {
+		if !day.Before(truncateToDate(change.EffectiveFrom)) {
+			rate = change.Rate
+		}
+	}
+	return rate
+}
+
+// bookedPaymentAt is the installment a loan charged rate over its whole
+// booked term would have, rounded the way NewLoan rounds it.
+func (l *Loan) bookedPaymentAt(rate float64) Money {
+	return roundTo(roundTo(l.PrincipalAmount+l.PrincipalAmount*rate, 2)/float64(l.bookedTermWeeks()), 2)
+}
+
+// weeklyPaymentAt is the regular installment at rate under the loan's latest
+// terms: the booked one, or the one its last prepayment reamortized to.
+func (l *Loan) weeklyPaymentAt(rate float64) Money {
+	if len(l.Prepayments) == 0 {
+		return l.bookedPaymentAt(rate)
+	}
+	return roundTo(l.regularPrincipal()*(1+rate), 2)
+}
+
+// installmentDue is the regular installment of week, due on due. It is
+// WeeklyPaymentAmount unless the loan has been repriced or prepaid since, or
+// the week falls in a zero-interest window, or it is an interest-only week
+// of a structured loan.
+func (l *Loan) installmentDue(week int, due time.Time) Money {
+	if installment, ok := l.structuredInstallment(week); ok {
+		return installment
+	}
+	rate := l.chargedRateOn(due)
+	if p := l.prepaymentBefore(week); p != nil {
+		return roundTo(p.WeeklyPrincipal*(1+rate), 2)
+	}
+	if len(l.Prepayments) == 0 && math.Abs(rate-l.InterestRate) <= rateTolerance {
+		return l.WeeklyPaymentAmount
+	}
+	return l.bookedPaymentAt(rate)
+}
+
+// PlanRepricing works out what charging rate, rounded to four decimals, from
+// effectiveFrom on does to l, whose RateHistory and Adjustments must be
+// complete, and to current, its stored schedule. The interest of each
+// installment is the share of the term's interest at the rate in effect on
+// its due date, so installments due before effectiveFrom keep their amount.
+// Installments that are due from then on and already carry a payment cannot
+// be recalculated, which makes the change ErrConflict, as does an effective
+// date not after the loan's latest rate change. A paid-off loan is not
+// repriced.
+func PlanRepricing(l *Loan, current []ScheduleEntry, rate float64, effectiveFrom time.Time) (*Repricing, error) {
+	rate, err := validateRepricing(rate, effectiveFrom)
+	if err != nil {
+		return nil, err
+	}
+	effectiveFrom = truncateToDate(effectiveFrom)
+	if effectiveFrom.Before(truncateToDate(l.StartDate)) {
+		return nil, fmt.Errorf("%w: effective date %s is before loan %d started", apperrors.ErrInvalidArgument, effectiveFrom.Format("2006-01-02"), l.ID)
+	}
+	if l.Status == StatusPaidOff {
+		return nil, fmt.Errorf("%w: loan %d is paid off", apperrors.ErrConflict, l.ID)
+	}
+	if err := l.checkReshapable("repricing"); err != nil {
+		return nil, err
+	}
+	if n := len(l.RateHistory); n > 0 && !effectiveFrom.After(truncateToDate(l.RateHistory[n-1].EffectiveFrom)) {
+		return nil, fmt.Errorf("%w: loan %d was repriced from %s; a new rate must take effect later", apperrors.ErrConflict, l.ID, l.RateHistory[n-1].EffectiveFrom.Format("2006-01-02"))
+	}
+	if math.Abs(rate-l.InterestRate) <= rateTolerance {
+		return nil, fmt.Errorf("%w: loan %d is already charged %v", apperrors.ErrConflict, l.ID, rate)
+	}
+
+	repriced := *l
+	repriced.RateHistory = append(append([]RateChange{}, l.RateHistory...), RateChange{
+		LoanID:        l.ID,
+		PreviousRate:  l.InterestRate,
+		Rate:          rate,
+		EffectiveFrom: effectiveFrom,
+	})
+	repriced.InterestRate = rate
+	repriced.WeeklyPaymentAmount = repriced.weeklyPaymentAt(rate)
+	repriced.TotalLoanAmount = repriced.totalFromTerms()
+
+	expected, err := repriced.GenerateSchedule()
+	if err != nil {
+		return nil, err
+	}
+	amounts := make(map[int]Money, len(expected))
+	for _, entry := range expected {
+		amounts[entry.WeekNumber] = entry.DueAmount
+	}
+
+	plan := &Repricing{
+		Change:              repriced.RateHistory[len(repriced.RateHistory)-1],
+		Changes:             []ScheduleChange{},
+		WeeklyPaymentAmount: repriced.WeeklyPaymentAmount,
+		TotalLoanAmount:     repriced.TotalLoanAmount,
+	}
+	affected := 0
+	for i := range current {
+		have := &current[i]
+		want, ok := amounts[have.WeekNumber]
+		if !ok || truncateToDate(have.DueDate).Before(effectiveFrom) {
+			continue
+		}
+		affected++
+		if have.hasPayment() {
+			return nil, fmt.Errorf("%w: week %d of loan %d, due on %s, is already paid; the new rate can only take effect after it",
+				apperrors.ErrConflict, have.WeekNumber, l.ID, have.DueDate.Format("2006-01-02"))
+		}
+		if math.Abs(have.DueAmount-want) <= centTolerance {
+			continue
+		}
+		after := *have
+		after.DueAmount = want
+		plan.Changes = append(plan.Changes, ScheduleChange{Kind: ScheduleEntryUpdated, WeekNumber: have.WeekNumber, Before: have, After: &after})
+	}
+	if affected == 0 {
+		return nil, fmt.Errorf("%w: loan %d has no installment due on or after %s", apperrors.ErrInvalidArgument, l.ID, effectiveFrom.Format("2006-01-02"))
+	}
+	return plan, nil
+}
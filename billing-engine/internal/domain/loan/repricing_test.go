@@ -0,0 +1,148 @@
+package loan
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanRepricing(t *testing.T) {
+	start := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	paidAt := time.Date(2025, 1, 13, 10, 0, 0, 0, time.UTC)
+
+	// newStored returns a three week loan of 110 a week, due on 13, 20 and 27
+	// January, and the schedule it was booked with.
+	newStored := func(t *testing.T) (*Loan, []ScheduleEntry) {
+		l, err := NewLoan(300, 3, 0.1, start)
+		require.NoError(t, err)
+		l.ID = 7
+		schedule, err := l.GenerateSchedule()
+		require.NoError(t, err)
+		for i := range schedule {
+			schedule[i].ID = int64(i + 1)
+			schedule[i].LoanID = l.ID
+		}
+		return l, schedule
+	}
+
+	t.Run("recalculates the installments due from the effective date", func(t *testing.T) {
+		l, stored := newStored(t)
+		stored[0].Status = PaymentStatusPaid
+		stored[0].PaidAmount = 110
+		stored[0].PaymentDate = &paidAt
+
+		plan, err := PlanRepricing(l, stored, 0.16, time.Date(2025, 1, 20, 15, 0, 0, 0, time.UTC))
+
+		require.NoError(t, err)
+		assert.Equal(t, RateChange{LoanID: 7, PreviousRate: 0.1, Rate: 0.16, EffectiveFrom: time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC)}, plan.Change)
+		assert.Equal(t, 116.0, plan.WeeklyPaymentAmount)
+		assert.Equal(t, 342.0, plan.TotalLoanAmount, "10 interest on the first week and 16 on each of the others")
+		require.Len(t, plan.Changes, 2)
+		for i, change := range plan.Changes {
+			assert.Equal(t, ScheduleEntryUpdated, change.Kind)
+			assert.Equal(t, i+2, change.WeekNumber)
+			assert.Equal(t, 110.0, change.Before.DueAmount)
+			assert.Equal(t, 116.0, change.After.DueAmount)
+			assert.Equal(t, PaymentStatusPending, change.After.Status)
+		}
+	})
+
+	t.Run("keeps earlier changes in effect", func(t *testing.T) {
+		l, stored := newStored(t)
+		l.RateHistory = []RateChange{{LoanID: 7, PreviousRate: 0.1, Rate: 0.16, EffectiveFrom: time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC)}}
+		l.InterestRate = 0.16
+		l.WeeklyPaymentAmount = 116
+		l.TotalLoanAmount = 342
+		stored[1].DueAmount = 116
+		stored[2].DueAmount = 116
+
+		plan, err := PlanRepricing(l, stored, 0.04, time.Date(2025, 1, 27, 0, 0, 0, 0, time.UTC))
+
+		require.NoError(t, err)
+		assert.Equal(t, 0.16, plan.Change.PreviousRate)
+		assert.Equal(t, 330.0, plan.TotalLoanAmount, "10, then 16, then 4 interest")
+		require.Len(t, plan.Changes, 1)
+		assert.Equal(t, 3, plan.Changes[0].WeekNumber)
+		assert.Equal(t, 104.0, plan.Changes[0].After.DueAmount)
+	})
+
+	t.Run("rejects a rate change over a paid installment", func(t *testing.T) {
+		l, stored := newStored(t)
+		stored[1].PaidAmount = 50
+
+		_, err := PlanRepricing(l, stored, 0.16, time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC))
+
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+	})
+
+	t.Run("rejects an effective date not after the latest change", func(t *testing.T) {
+		l, stored := newStored(t)
+		l.RateHistory = []RateChange{{LoanID: 7, PreviousRate: 0.1, Rate: 0.16, EffectiveFrom: time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC)}}
+		l.InterestRate = 0.16
+
+		_, err := PlanRepricing(l, stored, 0.12, time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC))
+
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+	})
+
+	t.Run("rejects the rate the loan already has", func(t *testing.T) {
+		l, stored := newStored(t)
+
+		_, err := PlanRepricing(l, stored, 0.10001, time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC))
+
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+	})
+
+	t.Run("rejects a paid-off loan", func(t *testing.T) {
+		l, stored := newStored(t)
+		l.Status = StatusPaidOff
+
+		_, err := PlanRepricing(l, stored, 0.16, time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC))
+
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+	})
+
+	t.Run("rejects dates outside the term", func(t *testing.T) {
+		l, stored := newStored(t)
+
+		_, err := PlanRepricing(l, stored, 0.16, time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC))
+		assert.ErrorIs(t, err, apperrors.ErrInvalidArgument, "before the start")
+
+		_, err = PlanRepricing(l, stored, 0.16, time.Date(2025, 1, 28, 0, 0, 0, 0, time.UTC))
+		assert.ErrorIs(t, err, apperrors.ErrInvalidArgument, "after the last installment")
+	})
+
+	t.Run("rejects rates the loan cannot hold", func(t *testing.T) {
+		l, stored := newStored(t)
+		for _, rate := range []float64{-0.01, 10} {
+			_, err := PlanRepricing(l, stored, rate, time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC))
+			assert.ErrorIs(t, err, apperrors.ErrInvalidArgument, "rate %v", rate)
+		}
+		_, err := PlanRepricing(l, stored, 0.16, time.Time{})
+		assert.ErrorIs(t, err, apperrors.ErrInvalidArgument, "no effective date")
+	})
+}
+
+func TestGenerateScheduleFollowsRateHistory(t *testing.T) {
+	l, err := NewLoan(1000, 4, 0.1, time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	l.RateHistory = []RateChange{
+		{PreviousRate: 0.1, Rate: 0.2, EffectiveFrom: time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC)},
+		{PreviousRate: 0.2, Rate: 0.05, EffectiveFrom: time.Date(2025, 1, 27, 0, 0, 0, 0, time.UTC)},
+	}
+	l.InterestRate = 0.05
+	l.WeeklyPaymentAmount = l.weeklyPaymentAt(0.05)
+	l.TotalLoanAmount = 1000 + 25 + 50 + 12.5 + 12.5
+
+	schedule, err := l.GenerateSchedule()
+
+	require.NoError(t, err)
+	due := make([]Money, len(schedule))
+	for i, entry := range schedule {
+		due[i] = entry.DueAmount
+	}
+	assert.Equal(t, []Money{275, 300, 262.5, 262.5}, due)
+}
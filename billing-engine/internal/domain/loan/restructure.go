@@ -0,0 +1,160 @@
+package loan
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"fmt"
+	"time"
+)
+
+// RestructureKind is the change to a loan's terms a restructure preview
+// works out.
+type RestructureKind string
+
+const (
+	RestructureRepricing      RestructureKind = "REPRICING"
+	RestructurePaymentHoliday RestructureKind = RestructureKind(AdjustmentPaymentHoliday)
+	RestructureZeroInterest   RestructureKind = RestructureKind(AdjustmentZeroInterest)
+	RestructurePrepayment     RestructureKind = "PREPAYMENT"
+)
+
+// previewReason stands in for the reason an applied adjustment must give,
+// which a preview does not ask for.
+const previewReason = "restructure preview"
+
+// Restructure is a repricing, an adjustment or a prepayment that has not
+// been made. Only the fields of its Kind are read: Rate and EffectiveFrom
+// for a repricing, StartsOn and Weeks for a payment holiday or a
+// zero-interest window, Amount and Option for a prepayment.
+type Restructure struct {
+	Kind          RestructureKind
+	Rate          float64
+	EffectiveFrom time.Time
+	StartsOn      time.Time
+	Weeks         int
+	Amount        Money
+	Option        PrepaymentOption
+}
+
+func validateRestructure(r Restructure) error {
+	switch r.Kind {
+	case RestructureRepricing:
+		_, err := validateRepricing(r.Rate, r.EffectiveFrom)
+		return err
+	case RestructurePaymentHoliday, RestructureZeroInterest:
+		return validateAdjustment(r.adjustment())
+	case RestructurePrepayment:
+		_, err := validatePrepayment(r.Amount, r.Option)
+		return err
+	}
+	return fmt.Errorf("%w: restructure kind must be %s, %s, %s or %s", apperrors.ErrInvalidArgument,
+		RestructureRepricing, RestructurePaymentHoliday, RestructureZeroInterest, RestructurePrepayment)
+}
+
+func (r Restructure) adjustment() *ScheduleAdjustment {
+	return &ScheduleAdjustment{Kind: AdjustmentKind(r.Kind), StartsOn: r.StartsOn, Weeks: r.Weeks, Reason: previewReason}
+}
+
+// LoanTerms is what a loan costs and when it ends, read off its terms and
+// schedule. TotalInterest is TotalLoanAmount less the principal, and
+// EndDate is when the last installment falls due.
+type LoanTerms struct {
+	InterestRate        float64
+	TermWeeks           int
+	WeeklyPaymentAmount Money
+	TotalLoanAmount     Money
+	TotalInterest       Money
+	EndDate             time.Time
+}
+
+// RestructurePreview is a restructure's diff of the schedule together with
+// the loan's terms before and after it.
+type RestructurePreview struct {
+	LoanID      int64
+	Restructure Restructure
+	Changes     []ScheduleChange
+	Before      LoanTerms
+	After       LoanTerms
+}
+
+// PlanRestructure works out what r would do to l, whose RateHistory,
+// Adjustments and Prepayments must be complete, and to current, its stored
+// schedule, with the planner that would make the change, so that a preview
+// is refused for the same reasons the change would be. today is the billing
+// date. Nothing is written.
+func PlanRestructure(l *Loan, current []ScheduleEntry, r Restructure, today time.Time) (*RestructurePreview, error) {
+	if err := validateRestructure(r); err != nil {
+		return nil, err
+	}
+	preview := &RestructurePreview{LoanID: l.ID, Restructure: r, Before: termsOf(l, current)}
+	after := *l
+	switch r.Kind {
+	case RestructureRepricing:
+		plan, err := PlanRepricing(l, current, r.Rate, r.EffectiveFrom)
+		if err != nil {
+			return nil, err
+		}
+		preview.Changes = plan.Changes
+		after.InterestRate = plan.Change.Rate
+		after.WeeklyPaymentAmount = plan.WeeklyPaymentAmount
+		after.TotalLoanAmount = plan.TotalLoanAmount
+	case RestructurePaymentHoliday, RestructureZeroInterest:
+		plan, err := PlanScheduleAdjustment(l, current, *r.adjustment(), today)
+		if err != nil {
+			return nil, err
+		}
+		preview.Changes = plan.Changes
+		after.TotalLoanAmount = plan.TotalLoanAmount
+	case RestructurePrepayment:
+		plan, err := PlanPrepayment(l, current, r.Amount, r.Option, today)
+		if err != nil {
+			return nil, err
+		}
+		preview.Changes = plan.Changes
+		after.TermWeeks = plan.Prepayment.TermWeeks
+		after.WeeklyPaymentAmount = plan.Prepayment.WeeklyPaymentAmount
+		after.TotalLoanAmount = plan.Prepayment.TotalLoanAmount
+	}
+	preview.After = termsOf(&after, applyChanges(current, preview.Changes))
+	return preview, nil
+}
+
+func termsOf(l *Loan, schedule []ScheduleEntry) LoanTerms {
+	terms := LoanTerms{
+		InterestRate:        l.InterestRate,
+		TermWeeks:           l.TermWeeks,
+		WeeklyPaymentAmount: l.WeeklyPaymentAmount,
+		TotalLoanAmount:     l.TotalLoanAmount,
+		TotalInterest:       roundTo(l.TotalLoanAmount-l.PrincipalAmount, 2),
+	}
+	for i := range schedule {
+		if schedule[i].DueDate.After(terms.EndDate) {
+			terms.EndDate = schedule[i].DueDate
+		}
+	}
+	return terms
+}
+
+// applyChanges returns the schedule current becomes once changes are
+// written, leaving current as it is.
+func applyChanges(current []ScheduleEntry, changes []ScheduleChange) []ScheduleEntry {
+	byWeek := make(map[int]ScheduleChange, len(changes))
+	for _, change := range changes {
+		byWeek[change.WeekNumber] = change
+	}
+	schedule := make([]ScheduleEntry, 0, len(current)+len(changes))
+	for _, entry := range current {
+		change, ok := byWeek[entry.WeekNumber]
+		switch {
+		case !ok:
+			schedule = append(schedule, entry)
+		case change.Kind == ScheduleEntryUpdated:
+			schedule = append(schedule, *change.After)
+		}
+	}
+	for _, change := range changes {
+		if change.Kind == ScheduleEntryAdded {
+			schedule = append(schedule, *change.After)
+		}
+	}
+	return schedule
+}
@@ -0,0 +1,105 @@
+package loan
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanRestructure(t *testing.T) {
+	start := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	today := time.Date(2025, 1, 14, 9, 0, 0, 0, time.UTC)
+	paidAt := time.Date(2025, 1, 13, 10, 0, 0, 0, time.UTC)
+
+	// newStored returns a three week loan of 110 a week, due on 13, 20 and 27
+	// January, whose first week is paid.
+	newStored := func(t *testing.T) (*Loan, []ScheduleEntry) {
+		l, err := NewLoan(300, 3, 0.1, start)
+		require.NoError(t, err)
+		l.ID = 7
+		schedule, err := l.GenerateSchedule()
+		require.NoError(t, err)
+		for i := range schedule {
+			schedule[i].ID = int64(i + 1)
+			schedule[i].LoanID = l.ID
+		}
+		schedule[0].Status = PaymentStatusPaid
+		schedule[0].PaidAmount = 110
+		schedule[0].PaymentDate = &paidAt
+		return l, schedule
+	}
+	before := LoanTerms{InterestRate: 0.1, TermWeeks: 3, WeeklyPaymentAmount: 110, TotalLoanAmount: 330, TotalInterest: 30,
+		EndDate: time.Date(2025, 1, 27, 0, 0, 0, 0, time.UTC)}
+
+	t.Run("repricing", func(t *testing.T) {
+		l, stored := newStored(t)
+
+		preview, err := PlanRestructure(l, stored, Restructure{Kind: RestructureRepricing, Rate: 0.16, EffectiveFrom: time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC)}, today)
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), preview.LoanID)
+		assert.Equal(t, before, preview.Before)
+		assert.Equal(t, LoanTerms{InterestRate: 0.16, TermWeeks: 3, WeeklyPaymentAmount: 116, TotalLoanAmount: 342, TotalInterest: 42,
+			EndDate: time.Date(2025, 1, 27, 0, 0, 0, 0, time.UTC)}, preview.After)
+		require.Len(t, preview.Changes, 2)
+		assert.Equal(t, 116.0, preview.Changes[1].After.DueAmount)
+		assert.Equal(t, 110.0, stored[2].DueAmount, "the stored schedule is left alone")
+	})
+
+	t.Run("payment holiday", func(t *testing.T) {
+		l, stored := newStored(t)
+
+		preview, err := PlanRestructure(l, stored, Restructure{Kind: RestructurePaymentHoliday, StartsOn: time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC), Weeks: 2}, today)
+
+		require.NoError(t, err)
+		require.Len(t, preview.Changes, 2)
+		assert.Equal(t, 330.0, preview.After.TotalLoanAmount, "a holiday adds no interest")
+		assert.Equal(t, time.Date(2025, 2, 10, 0, 0, 0, 0, time.UTC), preview.After.EndDate)
+		assert.Empty(t, l.Adjustments)
+	})
+
+	t.Run("zero interest", func(t *testing.T) {
+		l, stored := newStored(t)
+
+		preview, err := PlanRestructure(l, stored, Restructure{Kind: RestructureZeroInterest, StartsOn: time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC), Weeks: 1}, today)
+
+		require.NoError(t, err)
+		require.Len(t, preview.Changes, 1)
+		assert.Equal(t, 100.0, preview.Changes[0].After.DueAmount)
+		assert.Equal(t, 20.0, preview.After.TotalInterest)
+		assert.Equal(t, before.EndDate, preview.After.EndDate)
+	})
+
+	t.Run("prepayment", func(t *testing.T) {
+		l, stored := newStored(t)
+
+		preview, err := PlanRestructure(l, stored, Restructure{Kind: RestructurePrepayment, Amount: 100, Option: PrepaymentReduceTerm}, today)
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, preview.After.TermWeeks)
+		assert.Equal(t, 20.0, preview.After.TotalInterest, "the prepaid principal is charged no interest")
+		assert.Equal(t, time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC), preview.After.EndDate, "the last week is dropped")
+		assert.Equal(t, ScheduleEntryRemoved, preview.Changes[len(preview.Changes)-1].Kind)
+		assert.Empty(t, l.Prepayments)
+	})
+
+	t.Run("refused like the change", func(t *testing.T) {
+		l, stored := newStored(t)
+		l.Status = StatusPaidOff
+
+		_, err := PlanRestructure(l, stored, Restructure{Kind: RestructureRepricing, Rate: 0.16, EffectiveFrom: time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC)}, today)
+
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+	})
+
+	t.Run("rejects an unknown kind", func(t *testing.T) {
+		l, stored := newStored(t)
+
+		_, err := PlanRestructure(l, stored, Restructure{Kind: "REFINANCE"}, today)
+
+		assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+	})
+}
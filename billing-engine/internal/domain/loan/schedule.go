@@ -0,0 +1,66 @@
+package loan
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// centTolerance absorbs float noise when comparing amounts that are already
+// rounded to cents.
+const centTolerance = 0.005
+
+// CheckScheduleInvariants reports the first way schedule breaks the rules
+// GenerateSchedule promises for l:
+//
+//   - one entry per week, numbered from 1
+//   - due dates strictly increasing and after the start date
+//   - every installment but the last equals WeeklyPaymentAmount, or the
+//     weekly payment at the rate in effect on its due date once the loan
+//     has been repriced, or at no interest within a zero-interest window,
+//     or on the principal a prepayment reamortized it to, or the interest
+//     of an interest-only week
+//   - the last installment absorbs the rounding remainder, so the
+//     installments and prepayments add up to TotalLoanAmount to the cent
+func CheckScheduleInvariants(l *Loan, schedule []ScheduleEntry) error {
+	if len(schedule) == 0 {
+		return errors.New("schedule is empty")
+	}
+	if len(schedule) != l.TermWeeks {
+		return fmt.Errorf("schedule has %d installments, want %d", len(schedule), l.TermWeeks)
+	}
+
+	previousDue := l.StartDate
+	sumBeforeLast := 0.0
+	for i, entry := range schedule {
+		if entry.WeekNumber != i+1 {
+			return fmt.Errorf("installment %d has week number %d", i+1, entry.WeekNumber)
+		}
+		if !entry.DueDate.After(previousDue) {
+			return fmt.Errorf("week %d is due on %s, not after %s",
+				entry.WeekNumber, entry.DueDate.Format("2006-01-02"), previousDue.Format("2006-01-02"))
+		}
+		previousDue = entry.DueDate
+		if entry.DueAmount < 0 {
+			return fmt.Errorf("week %d has negative due amount %.2f", entry.WeekNumber, entry.DueAmount)
+		}
+		if i == len(schedule)-1 {
+			break
+		}
+		if want := l.installmentDue(entry.WeekNumber, entry.DueDate); math.Abs(entry.DueAmount-want) > centTolerance {
+			return fmt.Errorf("week %d is due %.2f, want the weekly payment %.2f",
+				entry.WeekNumber, entry.DueAmount, want)
+		}
+		sumBeforeLast += entry.DueAmount
+	}
+
+	last := schedule[len(schedule)-1]
+	installments := roundTo(l.TotalLoanAmount-l.prepaid(), 2)
+	if remainder := roundTo(installments-sumBeforeLast, 2); math.Abs(last.DueAmount-remainder) > centTolerance {
+		return fmt.Errorf("last installment is %.2f, want the remainder %.2f", last.DueAmount, remainder)
+	}
+	if total := sumBeforeLast + last.DueAmount; math.Abs(roundTo(total, 2)-installments) > centTolerance {
+		return fmt.Errorf("installments add up to %.2f, want %.2f", total, installments)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package loan
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"pgregory.net/rapid"
+)
+
+// drawLoan draws loan terms from the ranges the API accepts in practice:
+// up to two years of weekly installments on principals from 100k to 1bn.
+func drawLoan(t *rapid.T) *Loan {
+	principal := float64(rapid.Int64Range(100_000, 1_000_000_000).Draw(t, "principal"))
+	termWeeks := rapid.IntRange(1, 104).Draw(t, "termWeeks")
+	rate := float64(rapid.IntRange(0, 5000).Draw(t, "rateBasisPoints")) / 10_000
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, rapid.IntRange(0, 3650).Draw(t, "startOffsetDays"))
+
+	l, err := NewLoan(principal, termWeeks, rate, start)
+	require.NoError(t, err)
+	return l
+}
+
+func TestGenerateScheduleInvariants(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		l := drawLoan(t)
+		schedule, err := l.GenerateSchedule()
+		require.NoError(t, err)
+		require.NoError(t, CheckScheduleInvariants(l, schedule))
+	})
+}
+
+func TestGenerateScheduleFractionalPrincipal(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		cents := rapid.Int64Range(10_000, 100_000_000).Draw(t, "principalCents")
+		termWeeks := rapid.IntRange(1, 104).Draw(t, "termWeeks")
+		l, err := NewLoan(float64(cents)/100, termWeeks, 0.1, time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC))
+		require.NoError(t, err)
+
+		schedule, err := l.GenerateSchedule()
+		require.NoError(t, err)
+		require.NoError(t, CheckScheduleInvariants(l, schedule))
+	})
+}
+
+func TestCheckScheduleInvariants(t *testing.T) {
+	newSchedule := func(t *testing.T) (*Loan, []ScheduleEntry) {
+		l, err := NewLoan(1_000_003, 3, 0, time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC))
+		require.NoError(t, err)
+		schedule, err := l.GenerateSchedule()
+		require.NoError(t, err)
+		return l, schedule
+	}
+
+	t.Run("accepts a generated schedule", func(t *testing.T) {
+		l, schedule := newSchedule(t)
+		assert.NoError(t, CheckScheduleInvariants(l, schedule))
+		assert.InDelta(t, 333_334.34, schedule[2].DueAmount, 0.001, "the last week takes the remainder")
+	})
+
+	tests := []struct {
+		name    string
+		corrupt func(l *Loan, schedule []ScheduleEntry) []ScheduleEntry
+		want    string
+	}{
+		{
+			name:    "empty",
+			corrupt: func(*Loan, []ScheduleEntry) []ScheduleEntry { return nil },
+			want:    "schedule is empty",
+		},
+		{
+			name:    "missing installment",
+			corrupt: func(_ *Loan, s []ScheduleEntry) []ScheduleEntry { return s[:2] },
+			want:    "2 installments, want 3",
+		},
+		{
+			name: "week numbers out of order",
+			corrupt: func(_ *Loan, s []ScheduleEntry) []ScheduleEntry {
+				s[1].WeekNumber = 3
+				return s
+			},
+			want: "installment 2 has week number 3",
+		},
+		{
+			name: "due dates not increasing",
+			corrupt: func(_ *Loan, s []ScheduleEntry) []ScheduleEntry {
+				s[2].DueDate = s[1].DueDate
+				return s
+			},
+			want: "week 3 is due on 2025-01-20, not after 2025-01-20",
+		},
+		{
+			name: "first due date on the start date",
+			corrupt: func(l *Loan, s []ScheduleEntry) []ScheduleEntry {
+				s[0].DueDate = l.StartDate
+				return s
+			},
+			want: "week 1 is due on 2025-01-06",
+		},
+		{
+			name: "uneven installment",
+			corrupt: func(_ *Loan, s []ScheduleEntry) []ScheduleEntry {
+				s[0].DueAmount += 1
+				s[2].DueAmount -= 1
+				return s
+			},
+			want: "week 1 is due 333335.33, want the weekly payment 333334.33",
+		},
+		{
+			name: "remainder lost",
+			corrupt: func(_ *Loan, s []ScheduleEntry) []ScheduleEntry {
+				s[2].DueAmount = s[0].DueAmount
+				return s
+			},
+			want: "last installment is 333334.33, want the remainder 333334.34",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, schedule := newSchedule(t)
+			err := CheckScheduleInvariants(l, tt.corrupt(l, schedule))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package loan
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultSearchLimit is how many matches a search returns unless it
+	// asks for another number, at most MaxSearchLimit.
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+
+	// MaxSearchWindow bounds the paid window of a search by amount, whose
+	// matches are read from the date index rather than an index on amount.
+	MaxSearchWindow = 31 * 24 * time.Hour
+
+	// minSearchName keeps a name search from matching most of the book.
+	minSearchName = 3
+
+	// defaultSearchTolerance is how far, as a share of the amount, a paid
+	// amount may be off when the search does not say.
+	defaultSearchTolerance = 0.01
+)
+
+// LoanSearch finds loans from what a customer tells collections: a payment
+// reference, roughly how much was paid and when, or their name. Criteria
+// given together must all match.
+type LoanSearch struct {
+	// Reference matches a payment or prepayment posted with exactly this
+	// reference, through any channel.
+	Reference string
+	// Amount matches payments within Tolerance of it. It needs a paid
+	// window.
+	Amount *Money
+	// Tolerance is how far a paid amount may be from Amount; nil means 1%
+	// of it.
+	Tolerance *Money
+	// From and To keep payments with From <= paid_at < To.
+	From, To time.Time
+	// CustomerName matches customers whose name contains it, ignoring case.
+	CustomerName string
+	Limit        int
+}
+
+// SearchesPayments reports whether the search reads the payment ledger. A
+// search by name alone returns loans without a payment.
+func (q LoanSearch) SearchesPayments() bool {
+	return q.Reference != "" || q.Amount != nil
+}
+
+// AmountRange is the range of paid amounts a search by amount matches.
+func (q LoanSearch) AmountRange() (low, high Money) {
+	if q.Amount == nil {
+		return 0, 0
+	}
+	var tolerance Money
+	if q.Tolerance != nil {
+		tolerance = *q.Tolerance
+	}
+	return *q.Amount - tolerance, *q.Amount + tolerance
+}
+
+// normalize checks the search and fills in the default tolerance and limit.
+func (q LoanSearch) normalize() (LoanSearch, error) {
+	q.Reference = strings.TrimSpace(q.Reference)
+	q.CustomerName = strings.TrimSpace(q.CustomerName)
+	if !q.SearchesPayments() && q.CustomerName == "" {
+		return q, fmt.Errorf("%w: search needs a reference, an amount or a customer name", apperrors.ErrInvalidArgument)
+	}
+	if q.CustomerName != "" && utf8.RuneCountInString(q.CustomerName) < minSearchName {
+		return q, fmt.Errorf("%w: customer name must have at least %d characters", apperrors.ErrInvalidArgument, minSearchName)
+	}
+
+	hasWindow := !q.From.IsZero() || !q.To.IsZero()
+	if hasWindow && !q.SearchesPayments() {
+		return q, fmt.Errorf("%w: a paid window needs a reference or an amount", apperrors.ErrInvalidArgument)
+	}
+	if hasWindow && (q.From.IsZero() || q.To.IsZero()) {
+		return q, fmt.Errorf("%w: a paid window needs both from and to", apperrors.ErrInvalidArgument)
+	}
+	if hasWindow && !q.To.After(q.From) {
+		return q, fmt.Errorf("%w: to must be after from", apperrors.ErrInvalidArgument)
+	}
+
+	if q.Amount != nil {
+		if *q.Amount <= 0 || math.IsNaN(*q.Amount) {
+			return q, fmt.Errorf("%w: amount must be positive", apperrors.ErrInvalidArgument)
+		}
+		if !hasWindow {
+			return q, fmt.Errorf("%w: a search by amount needs from and to", apperrors.ErrInvalidArgument)
+		}
+		if q.To.Sub(q.From) > MaxSearchWindow {
+			return q, fmt.Errorf("%w: a search by amount covers at most %d days", apperrors.ErrInvalidArgument, int(MaxSearchWindow.Hours()/24))
+		}
+		if q.Tolerance == nil {
+			tolerance := roundTo(*q.Amount*defaultSearchTolerance, 2)
+			q.Tolerance = &tolerance
+		}
+		if *q.Tolerance < 0 || math.IsNaN(*q.Tolerance) {
+			return q, fmt.Errorf("%w: tolerance cannot be negative", apperrors.ErrInvalidArgument)
+		}
+	} else if q.Tolerance != nil {
+		return q, fmt.Errorf("%w: tolerance needs an amount", apperrors.ErrInvalidArgument)
+	}
+
+	switch {
+	case q.Limit == 0:
+		q.Limit = DefaultSearchLimit
+	case q.Limit < 0 || q.Limit > MaxSearchLimit:
+		return q, fmt.Errorf("%w: limit must be between 1 and %d", apperrors.ErrInvalidArgument, MaxSearchLimit)
+	}
+	return q, nil
+}
+
+// SearchMatch is a loan a search found. Payment is the ledger entry that
+// matched, nil for a search by name alone; a loan with several matching
+// payments is returned once for each.
+type SearchMatch struct {
+	LoanID       int64
+	LoanPublicID uuid.UUID
+	Status       LoanStatus
+	DaysPastDue  int
+	// CustomerID and CustomerName are nil when no customer holds the loan
+	// any more.
+	CustomerID   *int64
+	CustomerName *string
+	Payment      *PaymentMatch
+}
+
+// PaymentMatch is a payment or, when Prepayment is set, a prepayment that
+// matched a search.
+type PaymentMatch struct {
+	ID         int64
+	Amount     Money
+	Channel    PaymentChannel
+	Reference  *string
+	PaidAt     time.Time
+	Prepayment bool
+}
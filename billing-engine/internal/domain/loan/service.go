@@ -4,47 +4,224 @@ import (
 	"billing-engine/internal/domain/customer"
 	"billing-engine/internal/infrastructure/monitoring"
 	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"billing-engine/internal/pkg/scope"
 	"context"
 	"errors"
 	"fmt"
 	"log/slog"
 	"math"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 )
 
 type Money = float64
 
 type LoanService interface {
-	CreateLoan(ctx context.Context, customerID int64, principal Money, termWeeks int, annualInterestRate Money, startDate time.Time) (*Loan, error)
+	// CreateLoan books a loan with the schedule of product, one of the
+	// configured products; an empty product books a standard schedule.
+	CreateLoan(ctx context.Context, customerID int64, principal Money, termWeeks int, annualInterestRate Money, startDate time.Time, externalRef string, publicID uuid.UUID, product string) (*Loan, error)
 
+	// GetOutstanding is the Total of GetOutstandingBreakdown.
 	GetOutstanding(ctx context.Context, loanID int64) (Money, error)
 
+	// GetOutstandingBreakdown itemizes what is owed on the loan today:
+	// unpaid installments split into principal and interest, fees,
+	// penalties, and the suspense and credit balances held against them.
+	GetOutstandingBreakdown(ctx context.Context, loanID int64) (*OutstandingBreakdown, error)
+
 	IsDelinquent(ctx context.Context, loanID int64) (bool, error)
 
-	MakePayment(ctx context.Context, loanID int64, amount Money) error
+	// MakePayment applies amount to the oldest unpaid installment and records
+	// it in the payments ledger with details.
+	MakePayment(ctx context.Context, loanID int64, amount Money, details PaymentDetails) error
+
+	// CollectionsByChannel totals the payments received on the days from to
+	// to, inclusive, by channel. Days are UTC.
+	CollectionsByChannel(ctx context.Context, from, to time.Time) (*CollectionsReport, error)
+
+	// ListPayments returns the loan's latest limit payments, newest first.
+	ListPayments(ctx context.Context, loanID int64, limit int) ([]Payment, error)
+
+	// TaxReport totals the tax charged on the fees posted on the days from to
+	// to, inclusive, by jurisdiction and fee type. Days are UTC.
+	TaxReport(ctx context.Context, from, to time.Time) (*TaxReport, error)
+
+	// GetLoan returns the loan with its hold, rate history, adjustments and
+	// prepayments. The schedule is read, together with the loan, only when
+	// includeSchedule is set.
+	GetLoan(ctx context.Context, loanID int64, includeSchedule bool) (*Loan, error)
+
+	// PlaceHold puts the loan on an administrative hold, which blocks payments
+	// until ReleaseHold is called. placedBy names the staff member and may be
+	// empty.
+	PlaceHold(ctx context.Context, loanID int64, reason, placedBy string) (*Hold, error)
+
+	ReleaseHold(ctx context.Context, loanID int64, releasedBy string) (*Hold, error)
+
+	// RebuildSchedule regenerates the loan's unpaid schedule entries from its
+	// terms, leaving paid ones alone, and returns what changed. A dry run
+	// returns the same changes without writing them.
+	RebuildSchedule(ctx context.Context, loanID int64, dryRun bool) (*ScheduleRebuild, error)
+
+	// RepriceLoan charges rate on the loan's installments due on or after
+	// effectiveFrom, recalculates them and records the change in the loan's
+	// rate history. changedBy names the staff member and may be empty.
+	RepriceLoan(ctx context.Context, loanID int64, rate float64, effectiveFrom time.Time, changedBy string) (*Repricing, error)
+
+	// RepriceLoans applies a base-rate change to every loan filter selects.
+	// Loans that cannot take the new rate are reported as skipped instead of
+	// failing the batch.
+	RepriceLoans(ctx context.Context, filter RepricingFilter, rate float64, effectiveFrom time.Time, changedBy string) (*RepricingReport, error)
+
+	// ApplyScheduleAdjustment grants the loan a payment holiday or a
+	// zero-interest window and moves or recalculates the installments it
+	// affects. appliedBy names the staff member and may be empty.
+	ApplyScheduleAdjustment(ctx context.Context, loanID int64, adjustment ScheduleAdjustment, appliedBy string) (*AdjustmentPlan, error)
+
+	// RemoveScheduleAdjustment withdraws an adjustment that has not started
+	// and restores the installments it affected.
+	RemoveScheduleAdjustment(ctx context.Context, loanID, adjustmentID int64, removedBy string) (*AdjustmentPlan, error)
+
+	// Prepay takes a lump sum against the loan's principal and reamortizes
+	// its unpaid installments, shortening the term or lowering the
+	// installment as option says. The choice is recorded with the prepayment.
+	// recordedBy names the staff member and may be empty.
+	Prepay(ctx context.Context, loanID int64, amount Money, option PrepaymentOption, details PaymentDetails, recordedBy string) (*Reamortization, error)
+
+	// PreviewRestructure works out the schedule diff and the terms a
+	// repricing, adjustment or prepayment would give the loan, without
+	// making it.
+	PreviewRestructure(ctx context.Context, loanID int64, r Restructure) (*RestructurePreview, error)
 
-	GetLoan(ctx context.Context, loanID int64) (*Loan, error)
+	// PostFee charges the loan a fee from the catalog, owed on top of its
+	// installments. postedBy names the staff member and may be empty.
+	PostFee(ctx context.Context, loanID int64, feeType FeeType, amount Money, reason, postedBy string) (*Fee, error)
+
+	// ChargeLateFees posts policy's late fee for each installment of the
+	// loan still unpaid after the grace period that was not charged one
+	// yet, and returns the fees it posted. It posts none when the policy
+	// has no late fee or the loan is not active, and refuses a loan on
+	// hold with ErrLoanOnHold.
+	ChargeLateFees(ctx context.Context, loanID int64, policy DelinquencyPolicy) ([]Fee, error)
+
+	// GetFees returns the loan's fees and their waivers, oldest first.
+	GetFees(ctx context.Context, loanID int64) ([]Fee, error)
+
+	// RequestFeeWaiver asks for a fee to be written off. The fee stays owed
+	// until DecideFeeWaiver approves the request.
+	RequestFeeWaiver(ctx context.Context, loanID, feeID int64, reason, requestedBy string) (*FeeWaiver, error)
+
+	// DecideFeeWaiver approves or rejects the fee's pending waiver. Whoever
+	// requested it cannot decide it.
+	DecideFeeWaiver(ctx context.Context, loanID, feeID int64, approve bool, decidedBy string) (*FeeWaiver, error)
+
+	// SearchLoans finds loans by payment reference, approximate amount and
+	// paid window, or customer name, for staff who were given only one of
+	// them.
+	SearchLoans(ctx context.Context, q LoanSearch) ([]SearchMatch, error)
+
+	GetLoanByExternalRef(ctx context.Context, externalRef string, includeSchedule bool) (*Loan, error)
+
+	ResolveLoanID(ctx context.Context, publicID uuid.UUID) (int64, error)
 
 	GetLoanSchedule(ctx context.Context, loanID int64) ([]ScheduleEntry, error)
+
+	// GetLastModified reports when the loan, any of its schedule entries or
+	// its hold last changed, for conditional GETs.
+	GetLastModified(ctx context.Context, loanID int64) (time.Time, error)
+
+	// StreamLoans hands every loan matching filter to fn without loading the
+	// book into memory. Schedules are not included.
+	StreamLoans(ctx context.Context, filter LoanFilter, fn func(*Loan) error) error
 }
 
 type loanServiceImpl struct {
 	repo            Repository
 	customerService customer.CustomerService
+	payments        PaymentPolicy
+	delinquency     DelinquencyPolicySource
+	taxes           TaxPolicy
+	credit          CreditPolicy
+	products        ProductCatalog
+	clock           clock.Clock
 	logger          *slog.Logger
 }
 
-func NewLoanService(r Repository, cs customer.CustomerService, logger *slog.Logger) LoanService {
-	return &loanServiceImpl{repo: r, customerService: cs, logger: logger}
+// NewLoanService builds the loan service. payments decides which amounts
+// settle an installment, delinquency when a loan counts as delinquent,
+// taxes the tax charged on fees and interest, credit how much a customer
+// may borrow and products the schedules loans can be booked with. The
+// clock stamps payments and defaults the start date; nil means the wall
+// clock.
+func NewLoanService(r Repository, cs customer.CustomerService, payments PaymentPolicy, delinquency DelinquencyPolicySource, taxes TaxPolicy, credit CreditPolicy, products ProductCatalog, clk clock.Clock, logger *slog.Logger) LoanService {
+	return &loanServiceImpl{repo: r, customerService: cs, payments: payments, delinquency: delinquency, taxes: taxes, credit: credit, products: products, clock: clock.OrSystem(clk), logger: logger}
+}
+
+// authorizeTenant makes sure the loan belongs to ctx's tenant before a call
+// reaches it through its schedule, holds or fees, which do not record the
+// tenant. A loan of another tenant is not found. Unscoped contexts, such as
+// batch jobs, reach every tenant.
+func (s *loanServiceImpl) authorizeTenant(ctx context.Context, loanID int64) error {
+	if _, scoped := scope.TenantFromContext(ctx); !scoped {
+		return nil
+	}
+	_, err := s.loanFor(ctx, loanID, "tenant check")
+	return err
+}
+
+// authorizeLoanAccess enforces the tenant and the customer constraint
+// injected by the auth middleware. Unscoped (staff) contexts are always
+// allowed.
+func (s *loanServiceImpl) authorizeLoanAccess(ctx context.Context, loanID int64) error {
+	if err := s.authorizeTenant(ctx, loanID); err != nil {
+		return err
+	}
+	customerID, scoped := scope.CustomerFromContext(ctx)
+	if !scoped {
+		return nil
+	}
+
+	cust, err := s.customerService.GetCustomer(ctx, customerID)
+	if err != nil {
+		s.logger.Warn("Failed to resolve scoped customer", "customerID", customerID, "error", err)
+		return fmt.Errorf("%w: customer %d cannot access loan %d", apperrors.ErrForbidden, customerID, loanID)
+	}
+	if cust.LoanID == nil || *cust.LoanID != loanID {
+		s.logger.Warn("Scoped customer attempted to access foreign loan", "customerID", customerID, "loanID", loanID)
+		return fmt.Errorf("%w: customer %d cannot access loan %d", apperrors.ErrForbidden, customerID, loanID)
+	}
+	return nil
 }
 
-func (s *loanServiceImpl) CreateLoan(ctx context.Context, customerID int64, principal Money, termWeeks int, annualInterestRate Money, startDate time.Time) (*Loan, error) {
+func denyCustomerScope(ctx context.Context) error {
+	if customerID, scoped := scope.CustomerFromContext(ctx); scoped {
+		return fmt.Errorf("%w: customer %d has read-only access", apperrors.ErrForbidden, customerID)
+	}
+	return nil
+}
+
+// CreateLoan books a new loan for the customer. A non-nil publicID supplied by
+// the client makes the call idempotent: when the customer already holds the
+// loan with that ID it is returned instead of creating a second one. Each
+// application asks the credit bureau to score the customer again.
+//
+// The checks on the customer are repeated by the repository, which writes
+// the loan, its schedule and the customer's link to it in one transaction,
+// so a failure at any step leaves nothing behind. Events are sent only after
+// that transaction commits, by the streaming wrapper.
+func (s *loanServiceImpl) CreateLoan(ctx context.Context, customerID int64, principal Money, termWeeks int, annualInterestRate Money, startDate time.Time, externalRef string, publicID uuid.UUID, product string) (*Loan, error) {
 	s.logger.Info("Creating new loan")
+	if err := denyCustomerScope(ctx); err != nil {
+		return nil, err
+	}
 	cust, err := s.customerService.GetCustomer(ctx, customerID)
 	if err != nil {
-		if errors.Is(err, customer.ErrNotFound) || errors.Is(err, apperrors.ErrNotFound) {
+		if errors.Is(err, apperrors.ErrNotFound) {
 			s.logger.Error("Customer not found", slog.Any("error", err))
 			return nil, fmt.Errorf("%w: customer %d not found", apperrors.ErrValidation, customerID)
 		}
@@ -57,9 +234,25 @@ func (s *loanServiceImpl) CreateLoan(ctx context.Context, customerID int64, prin
 		return nil, fmt.Errorf("%w: customer %d is not active", apperrors.ErrValidation, customerID)
 	}
 
+	if publicID != uuid.Nil {
+		existing, err := s.repo.GetLoanByPublicID(ctx, publicID)
+		switch {
+		case err == nil:
+			if cust.LoanID == nil || *cust.LoanID != existing.ID {
+				s.logger.Warn("Public ID already used by a loan of another customer", "customerID", customerID)
+				return nil, fmt.Errorf("%w: public ID %s is already assigned to another loan", apperrors.ErrConflict, publicID)
+			}
+			s.logger.Info("Loan with this public ID already exists, returning it", "loanID", existing.ID)
+			return s.GetLoan(ctx, existing.ID, false)
+		case !errors.Is(err, apperrors.ErrNotFound):
+			s.logger.Error("Failed to look up loan public ID", "error", err)
+			return nil, fmt.Errorf("%w: failed to look up loan public ID: %v", apperrors.ErrInternalServer, err)
+		}
+	}
+
 	if cust.LoanID != nil {
 		existingLoanID := *cust.LoanID
-		existingLoan, err := s.GetLoan(ctx, existingLoanID)
+		existingLoan, err := s.GetLoan(ctx, existingLoanID, false)
 		if err != nil {
 			s.logger.Error("Failed to get existing loan details", "error", err)
 			return nil, fmt.Errorf("failed to get existing loan details: %w", err)
@@ -67,15 +260,41 @@ func (s *loanServiceImpl) CreateLoan(ctx context.Context, customerID int64, prin
 
 		if existingLoan.Status != StatusPaidOff {
 			s.logger.Error("Customer already has an assigned active loan")
-			return nil, fmt.Errorf("%w (LoanID: %d)", customer.ErrCustomerAlreadyHasLoan, existingLoanID)
+			return nil, apperrors.Wrap(customer.ErrCustomerAlreadyHasLoan, apperrors.CodeConflict,
+				fmt.Sprintf("%v (LoanID: %d)", customer.ErrCustomerAlreadyHasLoan, existingLoanID)).
+				WithMeta("loanId", strconv.FormatInt(existingLoanID, 10))
 		}
 	}
 
+	if startDate.IsZero() {
+		startDate = s.clock.Now().Truncate(24 * time.Hour)
+	}
 	loan, err := NewLoan(principal, termWeeks, annualInterestRate, startDate)
 	if err != nil {
 		s.logger.Error("Failed to create new loan object", "error", err)
 		return nil, fmt.Errorf("failed to create new loan object: %w", err)
 	}
+	structure, err := s.products.structureFor(product, loan.PrincipalAmount)
+	if err == nil {
+		err = loan.applyStructure(structure)
+	}
+	if err != nil {
+		s.logger.Warn("Loan product cannot be booked", "product", product, "error", err)
+		return nil, err
+	}
+	// The bureau answers later, so the check below uses the score stored
+	// from an earlier request and the new one serves the next application.
+	s.customerService.RequestRiskScore(ctx, cust, customer.RiskScoreLoanApplication, loan.PrincipalAmount)
+	if err := s.credit.check(cust, loan.PrincipalAmount); err != nil {
+		s.logger.Warn("Loan refused by the credit policy", "customerID", customerID, "error", err)
+		return nil, err
+	}
+	if externalRef = strings.TrimSpace(externalRef); externalRef != "" {
+		loan.ExternalRef = &externalRef
+	}
+	if publicID != uuid.Nil {
+		loan.PublicID = publicID
+	}
 
 	schedule, err := loan.GenerateSchedule()
 	if err != nil {
@@ -84,88 +303,211 @@ func (s *loanServiceImpl) CreateLoan(ctx context.Context, customerID int64, prin
 	}
 
 	createdLoan, err := s.repo.CreateLoan(ctx, customerID, loan, schedule)
+	if errors.Is(err, apperrors.ErrAlreadyExists) {
+		s.logger.Warn("Loan identifier already in use", "error", err)
+		if strings.Contains(err.Error(), "public_id") {
+			return nil, fmt.Errorf("%w: public ID %s is already assigned to another loan", apperrors.ErrAlreadyExists, loan.PublicID)
+		}
+		return nil, fmt.Errorf("%w: external reference %q is already assigned to another loan", apperrors.ErrAlreadyExists, externalRef)
+	}
+	if errors.Is(err, apperrors.ErrConflict) {
+		// The customer was deactivated or given another loan after the
+		// checks above; the repository kept nothing.
+		s.logger.Warn("Customer changed while the loan was created", "customerID", customerID, "error", err)
+		return nil, err
+	}
 	if err != nil {
 		s.logger.Error("Failed to save loan and schedule", "error", err)
 		return nil, fmt.Errorf("%w: failed to save loan and schedule: %v", apperrors.ErrInternalServer, err)
 	}
-
-	s.customerService.AssignLoanToCustomer(ctx, customerID, createdLoan.ID)
-	if err != nil {
-		s.logger.Error("Failed to assign loan to customer", "error", err)
-		return nil, fmt.Errorf("failed to assign loan to customer: %w", err)
-	}
 	s.logger.Info("Loan created successfully", "loanID", createdLoan.ID, "customerID", customerID)
+	monitoring.RecordLoanCreation()
 
 	return createdLoan, nil
 }
 
 func (s *loanServiceImpl) GetOutstanding(ctx context.Context, loanID int64) (Money, error) {
-	s.logger.Info("Getting total outstanding amount for loan", "loanID", loanID)
-	outstandingAmount, err := s.repo.GetTotalOutstandingAmount(ctx, loanID)
+	breakdown, err := s.GetOutstandingBreakdown(ctx, loanID)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
+		return 0, err
+	}
+	return breakdown.Total, nil
+}
+
+func (s *loanServiceImpl) GetOutstandingBreakdown(ctx context.Context, loanID int64) (*OutstandingBreakdown, error) {
+	s.logger.Info("Getting outstanding amount for loan", "loanID", loanID)
+	if err := s.authorizeLoanAccess(ctx, loanID); err != nil {
+		return nil, err
+	}
+	loan, err := s.repo.GetLoanByID(ctx, loanID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) || errors.Is(err, apperrors.ErrNotFound) {
 			s.logger.Warn("Loan not found", "loanID", loanID)
-			return 0, fmt.Errorf("%w: loan with ID %d not found", apperrors.ErrNotFound, loanID)
+			return nil, fmt.Errorf("%w: loan with ID %d not found", apperrors.ErrNotFound, loanID)
+		}
+		s.logger.Warn("Failed to get loan", "loanID", loanID, "error", err)
+		return nil, fmt.Errorf("%w: failed to get outstanding amount for loan %d: %v", apperrors.ErrInternalServer, loanID, err)
+	}
+	schedule, err := s.loanSchedule(ctx, loanID)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.repo.ListBalanceItems(ctx, loanID)
+	if err != nil {
+		s.logger.Warn("Failed to get balance items", "loanID", loanID, "error", err)
+		return nil, fmt.Errorf("%w: failed to get outstanding amount for loan %d: %v", apperrors.ErrInternalServer, loanID, err)
+	}
+	if loan.Prepayments, err = s.repo.GetPrepayments(ctx, loanID); err != nil {
+		s.logger.Warn("Failed to get prepayments", "loanID", loanID, "error", err)
+		return nil, fmt.Errorf("%w: failed to get outstanding amount for loan %d: %v", apperrors.ErrInternalServer, loanID, err)
+	}
+
+	breakdown := CalculateOutstanding(loan, schedule, items, s.clock.Now(), s.payments, s.taxes)
+	breakdown.TenantID, _ = scope.TenantFromContext(ctx)
+	return breakdown, nil
+}
+
+func (s *loanServiceImpl) GetLastModified(ctx context.Context, loanID int64) (time.Time, error) {
+	if err := s.authorizeLoanAccess(ctx, loanID); err != nil {
+		return time.Time{}, err
+	}
+	lastModified, err := s.repo.GetLastModified(ctx, loanID)
+	if err != nil {
+		if errors.Is(err, apperrors.ErrNotFound) || errors.Is(err, pgx.ErrNoRows) {
+			return time.Time{}, fmt.Errorf("%w: loan with ID %d not found", apperrors.ErrNotFound, loanID)
 		}
-		s.logger.Warn("Failed to get outstanding amount", "loanID", loanID, "error", err)
-		return 0, fmt.Errorf("%w: failed to get outstanding amount for loan %d: %v", apperrors.ErrInternalServer, loanID, err)
+		s.logger.Warn("Failed to get loan last modified time", "loanID", loanID, "error", err)
+		return time.Time{}, fmt.Errorf("%w: failed to get last modified time for loan %d: %v", apperrors.ErrInternalServer, loanID, err)
 	}
+	return lastModified, nil
+}
 
-	return outstandingAmount, nil
+func (s *loanServiceImpl) StreamLoans(ctx context.Context, filter LoanFilter, fn func(*Loan) error) error {
+	if err := denyCustomerScope(ctx); err != nil {
+		return err
+	}
+	if filter.AfterID < 0 {
+		return fmt.Errorf("%w: cursor must not be negative", apperrors.ErrInvalidArgument)
+	}
+	if filter.MinDaysPastDue < 0 {
+		return fmt.Errorf("%w: days past due filter must not be negative", apperrors.ErrInvalidArgument)
+	}
+	if err := s.repo.StreamLoans(ctx, filter, fn); err != nil {
+		return fmt.Errorf("failed to stream loans after %d: %w", filter.AfterID, err)
+	}
+	return nil
 }
 
+// IsDelinquent applies the delinquency policy to the loan's schedule. The
+// customer's current flag decides which of the policy's thresholds applies;
+// a loan without a customer is judged as unflagged.
 func (s *loanServiceImpl) IsDelinquent(ctx context.Context, loanID int64) (bool, error) {
 	s.logger.Info("Checking if loan is delinquent", "loanID", loanID)
-	lastTwoUnpaid, err := s.repo.GetLastTwoDueUnpaidSchedules(ctx, loanID)
+	if err := s.authorizeLoanAccess(ctx, loanID); err != nil {
+		return false, err
+	}
+	schedule, err := s.loanSchedule(ctx, loanID)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			s.logger.Warn("Loan not found", "loanID", loanID)
-			return false, fmt.Errorf("%w: loan with ID %d not found for delinquency check", apperrors.ErrNotFound, loanID)
-		}
 		s.logger.Warn("Failed to check delinquency", "loanID", loanID, "error", err)
+		return false, err
+	}
+
+	flagged := false
+	cust, err := s.customerService.FindCustomerByLoan(ctx, loanID)
+	switch {
+	case err == nil:
+		flagged = cust.IsDelinquent
+	case errors.Is(err, apperrors.ErrNotFound):
+	default:
+		s.logger.Warn("Failed to look up customer for delinquency check", "loanID", loanID, "error", err)
 		return false, fmt.Errorf("%w: failed to check delinquency for loan %d: %v", apperrors.ErrInternalServer, loanID, err)
 	}
 
-	return len(lastTwoUnpaid) >= 2, nil
+	policy, err := s.delinquency.Current(ctx)
+	if err != nil {
+		s.logger.Warn("Failed to read the delinquency policy", "loanID", loanID, "error", err)
+		return false, fmt.Errorf("failed to check delinquency for loan %d: %w", loanID, err)
+	}
+	return policy.Evaluate(schedule, s.clock.Now(), flagged), nil
 }
 
-func (s *loanServiceImpl) MakePayment(ctx context.Context, loanID int64, amount Money) (err error) {
-	s.logger.Info("Making payment", "loanID", loanID, "amount", amount)
-	tx, err := s.repo.BeginTx(ctx)
-	if err != nil {
-		s.logger.Error("Failed to begin transaction", "error", err)
-		return fmt.Errorf("%w: could not begin transaction: %v", apperrors.ErrInternalServer, err)
+func (s *loanServiceImpl) MakePayment(ctx context.Context, loanID int64, amount Money, details PaymentDetails) (err error) {
+	details.normalize()
+	s.logger.Info("Making payment", "loanID", loanID, "amount", amount, "channel", details.Channel)
+	if err := denyCustomerScope(ctx); err != nil {
+		return err
+	}
+	if err := details.Validate(); err != nil {
+		return err
 	}
+	if err := s.authorizeTenant(ctx, loanID); err != nil {
+		return err
+	}
+	defer func() { monitoring.RecordPayment(paymentOutcome(err)) }()
 
-	defer func() {
-		status := "failure_internal"
-		if errors.Is(err, apperrors.ErrInvalidPaymentAmount) {
-			s.logger.Error("Invalid payment amount", "loanID", loanID, "amount", amount, "error", err)
-			status = "failure_amount"
-		}
-		if errors.Is(err, apperrors.ErrLoanFullyPaid) {
-			s.logger.Error("Loan is already fully paid", "loanID", loanID, "error", err)
-			status = "failure_fully_paid"
-		}
-		monitoring.RecordPayment(status)
-		if p := recover(); p != nil {
-			s.logger.Error("Panic occurred during payment processing", "loanID", loanID, "error", p)
-			_ = s.repo.RollbackTx(ctx, tx)
-			panic(p)
-		} else if err != nil {
-			s.logger.Error("Rolling back transaction due to error :", "error", err)
-			_ = s.repo.RollbackTx(ctx, tx)
-		}
+	err = s.repo.WithinTransaction(ctx, func(tx TxRepository) error {
+		return s.applyPayment(ctx, tx, loanID, amount, details)
+	})
+	if errors.Is(err, apperrors.ErrDatabase) {
+		s.logger.Error("Payment transaction failed", "loanID", loanID, "error", err)
+		return fmt.Errorf("%w: could not process payment: %v", apperrors.ErrInternalServer, err)
+	}
+	if err != nil {
+		return err
+	}
+	s.logger.Info("Payment processed successfully", "loanID", loanID, "amount", amount)
+	monitoring.RecordPaymentReceived(string(details.Channel), amount)
+	return nil
+}
 
-	}()
+// paymentOutcome is the status label MakePayment records for err.
+func paymentOutcome(err error) string {
+	switch {
+	case err == nil:
+		return "success"
+	case errors.Is(err, apperrors.ErrInvalidPaymentAmount):
+		return "failure_amount"
+	case errors.Is(err, apperrors.ErrLoanFullyPaid):
+		return "failure_fully_paid"
+	case errors.Is(err, apperrors.ErrAlreadyExists):
+		return "failure_duplicate"
+	case errors.Is(err, apperrors.ErrConflict):
+		return "failure_concurrent"
+	case errors.Is(err, apperrors.ErrLoanOnHold):
+		return "failure_on_hold"
+	}
+	return "failure_internal"
+}
 
-	entry, err := s.repo.FindOldestUnpaidEntryForUpdate(ctx, tx, loanID)
+// applyPayment posts amount to the loan's oldest unpaid installment inside
+// tx or, when none is left, to its oldest open fee, marking the loan paid off
+// when nothing else is owed.
+func (s *loanServiceImpl) applyPayment(ctx context.Context, tx TxRepository, loanID int64, amount Money, details PaymentDetails) error {
+	// Without the loan lock a second payment blocks on the schedule row and,
+	// once the first commits, is checked against whatever installment that
+	// left due.
+	if err := tx.LockLoanForPayment(ctx, loanID); err != nil {
+		return err
+	}
+	hold, err := tx.GetActiveHold(ctx, loanID)
 	if err != nil {
-		if errors.Is(err, apperrors.ErrNotFound) {
-			s.logger.Error("Loan is already fully paid", "loanID", loanID, "error", err)
-			return apperrors.ErrLoanFullyPaid
-		}
+		s.logger.Error("Failed to check loan hold", "loanID", loanID, "error", err)
+		return fmt.Errorf("%w: could not check loan hold: %v", apperrors.ErrInternalServer, err)
+	}
+	if hold != nil {
+		s.logger.Warn("Payment rejected, loan is on hold", "loanID", loanID, "holdID", hold.ID)
+		return fmt.Errorf("%w: loan %d is on hold: %s", apperrors.ErrLoanOnHold, loanID, hold.Reason)
+	}
 
+	entry, err := tx.FindOldestUnpaidEntryForUpdate(ctx, loanID)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		// With every installment paid, payments go to the fees still owed.
+		if err := s.payFee(ctx, tx, loanID, amount, details); err != nil {
+			return err
+		}
+		return s.closeIfSettled(ctx, tx, loanID)
+	}
+	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			_, checkLoanErr := s.repo.GetLoanByID(ctx, loanID)
 			if errors.Is(checkLoanErr, pgx.ErrNoRows) || errors.Is(checkLoanErr, apperrors.ErrNotFound) {
@@ -179,54 +521,157 @@ func (s *loanServiceImpl) MakePayment(ctx context.Context, loanID int64, amount
 		return fmt.Errorf("%w: could not find schedule entry to pay: %v", apperrors.ErrInternalServer, err)
 	}
 
-	tolerance := 0.001
-	if math.Abs(amount-entry.DueAmount) > tolerance {
-		s.logger.Error("Payment amount does not match due amount", "loanID", loanID, "amount", amount, "dueAmount", entry.DueAmount)
-		return fmt.Errorf("%w: payment amount %.2f does not match due amount %.2f",
-			apperrors.ErrInvalidPaymentAmount, amount, entry.DueAmount)
+	if err := s.payments.Check(entry, amount); err != nil {
+		s.logger.Error("Payment amount does not match due amount", "loanID", loanID, "amount", amount,
+			"dueAmount", entry.DueAmount, "paidAmount", entry.PaidAmount)
+		return err
 	}
 
-	now := time.Now()
+	now := s.clock.Now()
 	entry.Status = PaymentStatusPaid
-	entry.PaidAmount = amount
+	entry.PaidAmount = s.payments.Round(entry.PaidAmount + amount)
 	entry.PaymentDate = &now
 	entry.UpdatedAt = now
 
-	err = s.repo.UpdateScheduleEntryInTx(ctx, tx, entry)
+	err = tx.UpdateScheduleEntry(ctx, entry)
 	if err != nil {
 		s.logger.Error("Failed to update schedule entry", "loanID", loanID, "error", err)
 		return fmt.Errorf("%w: could not update schedule entry: %v", apperrors.ErrInternalServer, err)
 	}
 
-	allPaid, err := s.repo.CheckIfAllPaymentsMadeInTx(ctx, tx, loanID)
+	payment := newPayment(loanID, s.payments.Round(amount), details, now)
+	payment.ScheduleID = &entry.ID
+	if err := s.recordPayment(ctx, tx, payment); err != nil {
+		return err
+	}
+	return s.closeIfSettled(ctx, tx, loanID)
+}
+
+// payFee posts amount to the loan's oldest fee that is neither paid nor
+// waived, which it must settle together with the fee's tax.
+func (s *loanServiceImpl) payFee(ctx context.Context, tx TxRepository, loanID int64, amount Money, details PaymentDetails) error {
+	fee, err := tx.FindOldestOpenFeeForUpdate(ctx, loanID)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		s.logger.Error("Loan is already fully paid", "loanID", loanID)
+		return apperrors.ErrLoanFullyPaid
+	}
+	if err != nil {
+		s.logger.Error("Failed to find fee to pay", "loanID", loanID, "error", err)
+		return fmt.Errorf("%w: could not find fee to pay: %v", apperrors.ErrInternalServer, err)
+	}
+	if err := s.payments.CheckFee(fee, amount); err != nil {
+		s.logger.Error("Payment amount does not match fee owed", "loanID", loanID, "feeID", fee.ID, "amount", amount, "owed", fee.Owed())
+		return err
+	}
+
+	now := s.clock.Now()
+	fee.PaidAt = &now
+	if err := tx.SettleFee(ctx, fee); err != nil {
+		s.logger.Error("Failed to settle fee", "loanID", loanID, "feeID", fee.ID, "error", err)
+		return fmt.Errorf("%w: could not settle fee: %v", apperrors.ErrInternalServer, err)
+	}
+
+	payment := newPayment(loanID, s.payments.Round(amount), details, now)
+	payment.FeeID = &fee.ID
+	return s.recordPayment(ctx, tx, payment)
+}
+
+func newPayment(loanID int64, amount Money, details PaymentDetails, paidAt time.Time) *Payment {
+	return &Payment{
+		LoanID:      loanID,
+		Amount:      amount,
+		Channel:     details.Channel,
+		Reference:   optional(details.Reference),
+		CollectorID: optional(details.CollectorID),
+		PaidAt:      paidAt,
+	}
+}
+
+func (s *loanServiceImpl) recordPayment(ctx context.Context, tx TxRepository, payment *Payment) error {
+	err := tx.RecordPayment(ctx, payment)
+	if errors.Is(err, apperrors.ErrAlreadyExists) {
+		reference := ""
+		if payment.Reference != nil {
+			reference = *payment.Reference
+		}
+		s.logger.Warn("Payment reference already posted", "loanID", payment.LoanID, "channel", payment.Channel, "reference", reference)
+		return fmt.Errorf("%w: a %s payment with reference %q was already posted", apperrors.ErrAlreadyExists, payment.Channel, reference)
+	}
+	if err != nil {
+		s.logger.Error("Failed to record payment", "loanID", payment.LoanID, "error", err)
+		return fmt.Errorf("%w: could not record payment: %v", apperrors.ErrInternalServer, err)
+	}
+	return nil
+}
+
+// closeIfSettled marks the loan paid off once every installment is paid and
+// every fee is paid or waived.
+func (s *loanServiceImpl) closeIfSettled(ctx context.Context, tx TxRepository, loanID int64) error {
+	allPaid, err := tx.CheckIfAllPaymentsMade(ctx, loanID)
 	if err != nil {
 		s.logger.Error("Failed to check if all payments are made", "loanID", loanID, "error", err)
 		return fmt.Errorf("%w: could not check if loan payments are complete: %v", apperrors.ErrInternalServer, err)
 	}
 
 	if allPaid {
-		err = s.repo.UpdateLoanStatusInTx(ctx, tx, loanID, StatusPaidOff)
+		err = tx.UpdateLoanStatus(ctx, loanID, StatusPaidOff)
 		if err != nil {
 			s.logger.Error("Failed to update loan status to paid off", "loanID", loanID, "error", err)
 			return fmt.Errorf("%w: could not update loan status to paid off: %v", apperrors.ErrInternalServer, err)
 		}
 	}
 
-	err = s.repo.CommitTx(ctx, tx)
+	return nil
+}
+
+func (s *loanServiceImpl) CollectionsByChannel(ctx context.Context, from, to time.Time) (*CollectionsReport, error) {
+	if err := denyCustomerScope(ctx); err != nil {
+		return nil, err
+	}
+	from, to = truncateToDate(from), truncateToDate(to)
+	if to.Before(from) {
+		return nil, fmt.Errorf("%w: from must not be after to", apperrors.ErrInvalidArgument)
+	}
+
+	rows, err := s.repo.SumPaymentsByChannel(ctx, from, to.AddDate(0, 0, 1))
 	if err != nil {
-		s.logger.Error("Failed to commit transaction", "loanID", loanID, "error", err)
-		return fmt.Errorf("%w: could not commit transaction: %v", apperrors.ErrInternalServer, err)
+		s.logger.Error("Failed to sum payments by channel", "from", from, "to", to, "error", err)
+		return nil, fmt.Errorf("%w: failed to sum payments by channel: %v", apperrors.ErrInternalServer, err)
 	}
-	monitoring.RecordPayment("success")
-	s.logger.Info("Payment processed successfully", "loanID", loanID, "amount", amount)
-	return nil
+	return newCollectionsReport(from, to, rows, s.payments.Round), nil
 }
 
-func (s *loanServiceImpl) GetLoan(ctx context.Context, loanID int64) (*Loan, error) {
-	s.logger.Info("Getting loan details", "loanID", loanID)
-	loan, err := s.repo.GetLoanByID(ctx, loanID)
+func (s *loanServiceImpl) TaxReport(ctx context.Context, from, to time.Time) (*TaxReport, error) {
+	if err := denyCustomerScope(ctx); err != nil {
+		return nil, err
+	}
+	from, to = truncateToDate(from), truncateToDate(to)
+	if to.Before(from) {
+		return nil, fmt.Errorf("%w: from must not be after to", apperrors.ErrInvalidArgument)
+	}
+
+	rows, err := s.repo.SumTaxLines(ctx, from, to.AddDate(0, 0, 1))
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
+		s.logger.Error("Failed to sum tax lines", "from", from, "to", to, "error", err)
+		return nil, fmt.Errorf("%w: failed to sum tax lines: %v", apperrors.ErrInternalServer, err)
+	}
+	return newTaxReport(from, to, rows, s.payments.Round), nil
+}
+
+func (s *loanServiceImpl) GetLoan(ctx context.Context, loanID int64, includeSchedule bool) (*Loan, error) {
+	s.logger.Info("Getting loan details", "loanID", loanID, "includeSchedule", includeSchedule)
+	if err := s.authorizeLoanAccess(ctx, loanID); err != nil {
+		return nil, err
+	}
+	var loan *Loan
+	var err error
+	if includeSchedule {
+		loan, err = s.repo.GetLoanWithSchedule(ctx, loanID)
+	} else {
+		loan, err = s.repo.GetLoanByID(ctx, loanID)
+	}
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) || errors.Is(err, apperrors.ErrNotFound) {
 			s.logger.Warn("Loan not found", "loanID", loanID)
 			return nil, fmt.Errorf("%w: loan with ID %d not found", apperrors.ErrNotFound, loanID)
 		}
@@ -235,20 +680,816 @@ func (s *loanServiceImpl) GetLoan(ctx context.Context, loanID int64) (*Loan, err
 		return nil, fmt.Errorf("%w: failed to get loan %d: %v", apperrors.ErrInternalServer, loanID, err)
 	}
 
-	schedule, err := s.GetLoanSchedule(ctx, loanID)
+	if _, scoped := scope.CustomerFromContext(ctx); !scoped {
+		hold, err := s.repo.GetActiveHold(ctx, loanID)
+		if err != nil {
+			s.logger.Error("Failed to get loan hold", "loanID", loanID, "error", err)
+			return nil, fmt.Errorf("%w: failed to get hold of loan %d: %v", apperrors.ErrInternalServer, loanID, err)
+		}
+		loan.Hold = hold
+	}
+
+	history, err := s.repo.GetRateHistory(ctx, loanID)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			s.logger.Warn("Loan not found", "loanID", loanID)
-		} else {
-			s.logger.Error("Failed to get loan schedule", "loanID", loanID, "error", err)
+		s.logger.Error("Failed to get loan rate history", "loanID", loanID, "error", err)
+		return nil, fmt.Errorf("%w: failed to get rate history of loan %d: %v", apperrors.ErrInternalServer, loanID, err)
+	}
+	if _, scoped := scope.CustomerFromContext(ctx); scoped {
+		for i := range history {
+			history[i].ChangedBy = nil
 		}
 	}
+	loan.RateHistory = history
 
-	loan.Schedule = schedule
+	adjustments, err := s.repo.GetScheduleAdjustments(ctx, loanID)
+	if err != nil {
+		s.logger.Error("Failed to get loan schedule adjustments", "loanID", loanID, "error", err)
+		return nil, fmt.Errorf("%w: failed to get schedule adjustments of loan %d: %v", apperrors.ErrInternalServer, loanID, err)
+	}
+	if _, scoped := scope.CustomerFromContext(ctx); scoped {
+		for i := range adjustments {
+			adjustments[i].AppliedBy, adjustments[i].RemovedBy = nil, nil
+		}
+	}
+	loan.Adjustments = adjustments
+
+	prepayments, err := s.repo.GetPrepayments(ctx, loanID)
+	if err != nil {
+		s.logger.Error("Failed to get loan prepayments", "loanID", loanID, "error", err)
+		return nil, fmt.Errorf("%w: failed to get prepayments of loan %d: %v", apperrors.ErrInternalServer, loanID, err)
+	}
+	if _, scoped := scope.CustomerFromContext(ctx); scoped {
+		for i := range prepayments {
+			prepayments[i].RecordedBy = nil
+		}
+	}
+	loan.Prepayments = prepayments
 	return loan, nil
 }
 
+func (s *loanServiceImpl) PlaceHold(ctx context.Context, loanID int64, reason, placedBy string) (*Hold, error) {
+	if err := denyCustomerScope(ctx); err != nil {
+		return nil, err
+	}
+	reason, err := validateHoldReason(reason)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorizeTenant(ctx, loanID); err != nil {
+		return nil, err
+	}
+
+	hold := &Hold{LoanID: loanID, Reason: reason, PlacedBy: optional(placedBy), PlacedAt: s.clock.Now()}
+	if err := s.repo.PlaceHold(ctx, hold); err != nil {
+		if errors.Is(err, apperrors.ErrNotFound) || errors.Is(err, apperrors.ErrConflict) {
+			return nil, err
+		}
+		s.logger.Error("Failed to place loan hold", "loanID", loanID, "error", err)
+		return nil, fmt.Errorf("%w: failed to place hold on loan %d: %v", apperrors.ErrInternalServer, loanID, err)
+	}
+	s.logger.Info("Loan placed on hold", "loanID", loanID, "holdID", hold.ID, "placedBy", placedBy)
+	return hold, nil
+}
+
+func (s *loanServiceImpl) ReleaseHold(ctx context.Context, loanID int64, releasedBy string) (*Hold, error) {
+	if err := denyCustomerScope(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.authorizeTenant(ctx, loanID); err != nil {
+		return nil, err
+	}
+	hold, err := s.repo.ReleaseHold(ctx, loanID, optional(releasedBy), s.clock.Now())
+	if err != nil {
+		if errors.Is(err, apperrors.ErrNotFound) {
+			return nil, err
+		}
+		s.logger.Error("Failed to release loan hold", "loanID", loanID, "error", err)
+		return nil, fmt.Errorf("%w: failed to release hold on loan %d: %v", apperrors.ErrInternalServer, loanID, err)
+	}
+	s.logger.Info("Loan hold released", "loanID", loanID, "holdID", hold.ID, "releasedBy", releasedBy)
+	return hold, nil
+}
+
+// RebuildSchedule takes the payment lock so that no payment lands between
+// reading the schedule and writing the rebuild.
+func (s *loanServiceImpl) RebuildSchedule(ctx context.Context, loanID int64, dryRun bool) (*ScheduleRebuild, error) {
+	if err := denyCustomerScope(ctx); err != nil {
+		return nil, err
+	}
+	l, err := s.loanFor(ctx, loanID, "schedule rebuild")
+	if err != nil {
+		return nil, err
+	}
+
+	var plan *ScheduleRebuild
+	err = s.repo.WithinTransaction(ctx, func(tx TxRepository) error {
+		current, err := s.lockForReshape(ctx, tx, l)
+		if err != nil {
+			return err
+		}
+		if plan, err = PlanScheduleRebuild(l, current); err != nil {
+			return err
+		}
+		plan.DryRun = dryRun
+		if dryRun {
+			return nil
+		}
+
+		if len(plan.Changes) > 0 {
+			if err := tx.ApplyScheduleChanges(ctx, loanID, plan.Changes); err != nil {
+				s.logger.Error("Failed to write rebuilt schedule", "loanID", loanID, "error", err)
+				return fmt.Errorf("%w: could not write rebuilt schedule: %v", apperrors.ErrInternalServer, err)
+			}
+		}
+		if plan.Status != l.Status {
+			if err := tx.UpdateLoanStatus(ctx, loanID, plan.Status); err != nil {
+				s.logger.Error("Failed to update loan status after schedule rebuild", "loanID", loanID, "status", plan.Status, "error", err)
+				return fmt.Errorf("%w: could not update loan status: %v", apperrors.ErrInternalServer, err)
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, apperrors.ErrDatabase) {
+		s.logger.Error("Schedule rebuild transaction failed", "loanID", loanID, "error", err)
+		return nil, fmt.Errorf("%w: could not rebuild schedule: %v", apperrors.ErrInternalServer, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("Loan schedule rebuilt", "loanID", loanID, "dryRun", dryRun, "changes", len(plan.Changes), "paidKept", plan.PaidKept, "status", plan.Status)
+	return plan, nil
+}
+
+func (s *loanServiceImpl) RepriceLoan(ctx context.Context, loanID int64, rate float64, effectiveFrom time.Time, changedBy string) (*Repricing, error) {
+	if err := denyCustomerScope(ctx); err != nil {
+		return nil, err
+	}
+	if _, err := validateRepricing(rate, effectiveFrom); err != nil {
+		return nil, err
+	}
+	l, err := s.loanFor(ctx, loanID, "repricing")
+	if err != nil {
+		return nil, err
+	}
+	return s.reprice(ctx, l, rate, effectiveFrom, changedBy)
+}
+
+// RepriceLoans collects the loans before repricing any of them and then
+// reprices each in a transaction of its own, so a loan that fails leaves
+// those before it repriced.
+func (s *loanServiceImpl) RepriceLoans(ctx context.Context, filter RepricingFilter, rate float64, effectiveFrom time.Time, changedBy string) (*RepricingReport, error) {
+	if err := denyCustomerScope(ctx); err != nil {
+		return nil, err
+	}
+	rate, err := validateRepricing(rate, effectiveFrom)
+	if err != nil {
+		return nil, err
+	}
+
+	var loans []*Loan
+	err = s.repo.StreamLoans(ctx, LoanFilter{}, func(l *Loan) error {
+		if l.Status == StatusPaidOff {
+			return nil
+		}
+		if filter.CurrentRate != nil && math.Abs(l.InterestRate-*filter.CurrentRate) > rateTolerance {
+			return nil
+		}
+		loans = append(loans, l)
+		return nil
+	})
+	if err != nil {
+		s.logger.Error("Failed to list loans for repricing", "error", err)
+		return nil, fmt.Errorf("%w: could not list loans: %v", apperrors.ErrInternalServer, err)
+	}
+
+	report := &RepricingReport{Rate: rate, EffectiveFrom: truncateToDate(effectiveFrom), Skipped: []RepricingSkip{}}
+	for _, l := range loans {
+		if _, err := s.reprice(ctx, l, rate, effectiveFrom, changedBy); err != nil {
+			if !errors.Is(err, apperrors.ErrConflict) && !errors.Is(err, apperrors.ErrInvalidArgument) && !errors.Is(err, apperrors.ErrLoanOnHold) {
+				return nil, err
+			}
+			report.Skipped = append(report.Skipped, RepricingSkip{LoanID: l.ID, Reason: err.Error()})
+			continue
+		}
+		report.Repriced++
+	}
+	s.logger.Info("Loans repriced", "rate", rate, "effectiveFrom", report.EffectiveFrom, "repriced", report.Repriced, "skipped", len(report.Skipped))
+	return report, nil
+}
+
+// lockForReshape takes the loan's payment lock, so that no payment lands
+// while its schedule is being reshaped, refuses a loan on hold and reads
+// under the lock what the schedule follows from: the rate history, the
+// adjustments, the prepayments and the stored schedule.
+// l was read before the lock, so its rate is taken from the history.
+func (s *loanServiceImpl) lockForReshape(ctx context.Context, tx TxRepository, l *Loan) ([]ScheduleEntry, error) {
+	if err := tx.LockLoanForPayment(ctx, l.ID); err != nil {
+		return nil, err
+	}
+	if err := s.refuseHold(ctx, tx, l.ID); err != nil {
+		return nil, err
+	}
+	current, err := tx.GetScheduleForUpdate(ctx, l.ID)
+	if err != nil {
+		s.logger.Error("Failed to read schedule", "loanID", l.ID, "error", err)
+		return nil, fmt.Errorf("%w: could not read schedule: %v", apperrors.ErrInternalServer, err)
+	}
+	if err := s.readReshapeHistory(ctx, tx, l); err != nil {
+		return nil, err
+	}
+	return current, nil
+}
+
+// holdReader is what both the repository and a transaction read of a
+// loan's holds.
+type holdReader interface {
+	GetActiveHold(ctx context.Context, loanID int64) (*Hold, error)
+}
+
+// refuseHold returns ErrLoanOnHold when the loan has an open hold.
+func (s *loanServiceImpl) refuseHold(ctx context.Context, r holdReader, loanID int64) error {
+	hold, err := r.GetActiveHold(ctx, loanID)
+	if err != nil {
+		s.logger.Error("Failed to check loan hold", "loanID", loanID, "error", err)
+		return fmt.Errorf("%w: could not check loan hold: %v", apperrors.ErrInternalServer, err)
+	}
+	if hold != nil {
+		s.logger.Warn("Change refused, loan is on hold", "loanID", loanID, "holdID", hold.ID)
+		return fmt.Errorf("%w: loan %d is on hold: %s", apperrors.ErrLoanOnHold, loanID, hold.Reason)
+	}
+	return nil
+}
+
+// reshapeHistory is what both the repository and a transaction read of the
+// changes a loan's schedule has been through.
+type reshapeHistory interface {
+	GetRateHistory(ctx context.Context, loanID int64) ([]RateChange, error)
+	GetScheduleAdjustments(ctx context.Context, loanID int64) ([]ScheduleAdjustment, error)
+	GetPrepayments(ctx context.Context, loanID int64) ([]Prepayment, error)
+}
+
+// readReshapeHistory fills in the rate history, adjustments and prepayments
+// of l that planning a reshape works from, and its current rate.
+func (s *loanServiceImpl) readReshapeHistory(ctx context.Context, r reshapeHistory, l *Loan) error {
+	var err error
+	if l.RateHistory, err = r.GetRateHistory(ctx, l.ID); err != nil {
+		s.logger.Error("Failed to read rate history", "loanID", l.ID, "error", err)
+		return fmt.Errorf("%w: could not read rate history: %v", apperrors.ErrInternalServer, err)
+	}
+	if n := len(l.RateHistory); n > 0 {
+		l.InterestRate = l.RateHistory[n-1].Rate
+	}
+	if l.Adjustments, err = r.GetScheduleAdjustments(ctx, l.ID); err != nil {
+		s.logger.Error("Failed to read schedule adjustments", "loanID", l.ID, "error", err)
+		return fmt.Errorf("%w: could not read schedule adjustments: %v", apperrors.ErrInternalServer, err)
+	}
+	if l.Prepayments, err = r.GetPrepayments(ctx, l.ID); err != nil {
+		s.logger.Error("Failed to read prepayments", "loanID", l.ID, "error", err)
+		return fmt.Errorf("%w: could not read prepayments: %v", apperrors.ErrInternalServer, err)
+	}
+	return nil
+}
+
+func (s *loanServiceImpl) reprice(ctx context.Context, l *Loan, rate float64, effectiveFrom time.Time, changedBy string) (*Repricing, error) {
+	var plan *Repricing
+	err := s.repo.WithinTransaction(ctx, func(tx TxRepository) error {
+		current, err := s.lockForReshape(ctx, tx, l)
+		if err != nil {
+			return err
+		}
+		if plan, err = PlanRepricing(l, current, rate, effectiveFrom); err != nil {
+			return err
+		}
+		plan.Change.ChangedBy = optional(changedBy)
+		plan.Change.CreatedAt = s.clock.Now()
+
+		if len(plan.Changes) > 0 {
+			if err := tx.ApplyScheduleChanges(ctx, l.ID, plan.Changes); err != nil {
+				s.logger.Error("Failed to write repriced schedule", "loanID", l.ID, "error", err)
+				return fmt.Errorf("%w: could not write repriced schedule: %v", apperrors.ErrInternalServer, err)
+			}
+		}
+		if err := tx.RepriceLoan(ctx, plan); err != nil {
+			s.logger.Error("Failed to store loan repricing", "loanID", l.ID, "error", err)
+			return fmt.Errorf("%w: could not store repricing: %v", apperrors.ErrInternalServer, err)
+		}
+		return nil
+	})
+	if errors.Is(err, apperrors.ErrDatabase) {
+		s.logger.Error("Repricing transaction failed", "loanID", l.ID, "error", err)
+		return nil, fmt.Errorf("%w: could not reprice loan %d: %v", apperrors.ErrInternalServer, l.ID, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("Loan repriced", "loanID", l.ID, "rate", rate, "previousRate", plan.Change.PreviousRate,
+		"effectiveFrom", plan.Change.EffectiveFrom, "installments", len(plan.Changes), "changedBy", changedBy)
+	return plan, nil
+}
+
+// loanFor reads the loan that purpose, such as a repricing, is about to
+// change.
+func (s *loanServiceImpl) loanFor(ctx context.Context, loanID int64, purpose string) (*Loan, error) {
+	l, err := s.repo.GetLoanByID(ctx, loanID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) || errors.Is(err, apperrors.ErrNotFound) {
+			return nil, fmt.Errorf("%w: loan with ID %d not found", apperrors.ErrNotFound, loanID)
+		}
+		s.logger.Error("Failed to load loan for "+purpose, "loanID", loanID, "error", err)
+		return nil, fmt.Errorf("%w: failed to get loan %d: %v", apperrors.ErrInternalServer, loanID, err)
+	}
+	return l, nil
+}
+
+func (s *loanServiceImpl) ApplyScheduleAdjustment(ctx context.Context, loanID int64, adjustment ScheduleAdjustment, appliedBy string) (*AdjustmentPlan, error) {
+	if err := denyCustomerScope(ctx); err != nil {
+		return nil, err
+	}
+	if err := validateAdjustment(&adjustment); err != nil {
+		return nil, err
+	}
+	l, err := s.loanFor(ctx, loanID, "schedule adjustment")
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	plan, err := s.reshapeSchedule(ctx, l, func(current []ScheduleEntry) (*AdjustmentPlan, error) {
+		return PlanScheduleAdjustment(l, current, adjustment, now)
+	}, func(tx TxRepository, plan *AdjustmentPlan) error {
+		plan.Adjustment.AppliedBy = optional(appliedBy)
+		plan.Adjustment.AppliedAt = now
+		return tx.AddScheduleAdjustment(ctx, plan)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("Schedule adjustment applied", "loanID", loanID, "adjustmentID", plan.Adjustment.ID, "kind", plan.Adjustment.Kind,
+		"startsOn", plan.Adjustment.StartsOn, "weeks", plan.Adjustment.Weeks, "installments", len(plan.Changes), "appliedBy", appliedBy)
+	return plan, nil
+}
+
+func (s *loanServiceImpl) RemoveScheduleAdjustment(ctx context.Context, loanID, adjustmentID int64, removedBy string) (*AdjustmentPlan, error) {
+	if err := denyCustomerScope(ctx); err != nil {
+		return nil, err
+	}
+	l, err := s.loanFor(ctx, loanID, "schedule adjustment removal")
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	plan, err := s.reshapeSchedule(ctx, l, func(current []ScheduleEntry) (*AdjustmentPlan, error) {
+		return PlanAdjustmentRemoval(l, current, adjustmentID, now)
+	}, func(tx TxRepository, plan *AdjustmentPlan) error {
+		plan.Adjustment.RemovedBy = optional(removedBy)
+		plan.Adjustment.RemovedAt = &now
+		return tx.RemoveScheduleAdjustment(ctx, plan)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("Schedule adjustment removed", "loanID", loanID, "adjustmentID", adjustmentID, "kind", plan.Adjustment.Kind,
+		"installments", len(plan.Changes), "removedBy", removedBy)
+	return plan, nil
+}
+
+// reshapeSchedule plans an adjustment change under the payment lock, writes
+// the installments it moves and then hands the plan to store.
+func (s *loanServiceImpl) reshapeSchedule(ctx context.Context, l *Loan, planFn func(current []ScheduleEntry) (*AdjustmentPlan, error), store func(tx TxRepository, plan *AdjustmentPlan) error) (*AdjustmentPlan, error) {
+	var plan *AdjustmentPlan
+	err := s.repo.WithinTransaction(ctx, func(tx TxRepository) error {
+		current, err := s.lockForReshape(ctx, tx, l)
+		if err != nil {
+			return err
+		}
+		if plan, err = planFn(current); err != nil {
+			return err
+		}
+		if len(plan.Changes) > 0 {
+			if err := tx.ApplyScheduleChanges(ctx, l.ID, plan.Changes); err != nil {
+				s.logger.Error("Failed to write adjusted schedule", "loanID", l.ID, "error", err)
+				return fmt.Errorf("%w: could not write adjusted schedule: %v", apperrors.ErrInternalServer, err)
+			}
+		}
+		if err := store(tx, plan); err != nil {
+			if errors.Is(err, apperrors.ErrNotFound) {
+				return err
+			}
+			s.logger.Error("Failed to store schedule adjustment", "loanID", l.ID, "error", err)
+			return fmt.Errorf("%w: could not store schedule adjustment: %v", apperrors.ErrInternalServer, err)
+		}
+		return nil
+	})
+	if errors.Is(err, apperrors.ErrDatabase) {
+		s.logger.Error("Schedule adjustment transaction failed", "loanID", l.ID, "error", err)
+		return nil, fmt.Errorf("%w: could not adjust the schedule of loan %d: %v", apperrors.ErrInternalServer, l.ID, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// Prepay is refused while the loan is on hold, as a payment and every other
+// change to the schedule would be.
+func (s *loanServiceImpl) Prepay(ctx context.Context, loanID int64, amount Money, option PrepaymentOption, details PaymentDetails, recordedBy string) (*Reamortization, error) {
+	details.normalize()
+	if err := denyCustomerScope(ctx); err != nil {
+		return nil, err
+	}
+	if err := details.Validate(); err != nil {
+		return nil, err
+	}
+	if _, err := validatePrepayment(amount, option); err != nil {
+		return nil, err
+	}
+	l, err := s.loanFor(ctx, loanID, "prepayment")
+	if err != nil {
+		return nil, err
+	}
+
+	var plan *Reamortization
+	err = s.repo.WithinTransaction(ctx, func(tx TxRepository) error {
+		current, err := s.lockForReshape(ctx, tx, l)
+		if err != nil {
+			return err
+		}
+
+		now := s.clock.Now()
+		if plan, err = PlanPrepayment(l, current, amount, option, now); err != nil {
+			return err
+		}
+		plan.Prepayment.Channel = details.Channel
+		plan.Prepayment.Reference = optional(details.Reference)
+		plan.Prepayment.RecordedBy = optional(recordedBy)
+		plan.Prepayment.PaidAt = now
+
+		if len(plan.Changes) > 0 {
+			if err := tx.ApplyScheduleChanges(ctx, loanID, plan.Changes); err != nil {
+				s.logger.Error("Failed to write reamortized schedule", "loanID", loanID, "error", err)
+				return fmt.Errorf("%w: could not write reamortized schedule: %v", apperrors.ErrInternalServer, err)
+			}
+		}
+		err = tx.RecordPrepayment(ctx, plan)
+		if errors.Is(err, apperrors.ErrAlreadyExists) {
+			return fmt.Errorf("%w: a %s prepayment with reference %q was already posted", apperrors.ErrAlreadyExists, details.Channel, details.Reference)
+		}
+		if err != nil {
+			s.logger.Error("Failed to record prepayment", "loanID", loanID, "error", err)
+			return fmt.Errorf("%w: could not record prepayment: %v", apperrors.ErrInternalServer, err)
+		}
+		return nil
+	})
+	if errors.Is(err, apperrors.ErrDatabase) {
+		s.logger.Error("Prepayment transaction failed", "loanID", loanID, "error", err)
+		return nil, fmt.Errorf("%w: could not prepay loan %d: %v", apperrors.ErrInternalServer, loanID, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	p := &plan.Prepayment
+	s.logger.Info("Loan prepaid", "loanID", loanID, "prepaymentID", p.ID, "amount", p.Amount, "option", p.Option,
+		"termWeeks", p.TermWeeks, "weeklyPaymentAmount", p.WeeklyPaymentAmount, "installments", len(plan.Changes), "recordedBy", recordedBy)
+	monitoring.RecordPaymentReceived(string(details.Channel), p.Amount)
+	return plan, nil
+}
+
+// PreviewRestructure plans the change on the stored schedule and refuses a
+// loan on hold as the change itself would be. It writes nothing and takes
+// no lock.
+func (s *loanServiceImpl) PreviewRestructure(ctx context.Context, loanID int64, r Restructure) (*RestructurePreview, error) {
+	if err := denyCustomerScope(ctx); err != nil {
+		return nil, err
+	}
+	if err := validateRestructure(r); err != nil {
+		return nil, err
+	}
+	l, err := s.loanFor(ctx, loanID, "restructure preview")
+	if err != nil {
+		return nil, err
+	}
+
+	// Unlike the change itself, the preview reads without the payment lock
+	// and FOR UPDATE, so that polling it never makes a payment on the loan
+	// fail with a conflict. A payment may overtake what it shows; the change
+	// is planned again under the lock when it is made.
+	current, err := s.repo.GetScheduleByLoanID(ctx, loanID)
+	if err != nil {
+		s.logger.Error("Failed to read schedule", "loanID", loanID, "error", err)
+		return nil, fmt.Errorf("%w: could not read schedule: %v", apperrors.ErrInternalServer, err)
+	}
+	if err := s.readReshapeHistory(ctx, s.repo, l); err != nil {
+		return nil, err
+	}
+	if err := s.refuseHold(ctx, s.repo, loanID); err != nil {
+		return nil, err
+	}
+	preview, err := PlanRestructure(l, current, r, s.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+	return preview, nil
+}
+
+func (s *loanServiceImpl) PostFee(ctx context.Context, loanID int64, feeType FeeType, amount Money, reason, postedBy string) (*Fee, error) {
+	if err := denyCustomerScope(ctx); err != nil {
+		return nil, err
+	}
+	amount, reason, err := validateFee(feeType, amount, reason)
+	if err != nil {
+		return nil, err
+	}
+	l, err := s.loanFor(ctx, loanID, "fee")
+	if err != nil {
+		return nil, err
+	}
+	if l.Status == StatusPaidOff {
+		return nil, fmt.Errorf("%w: loan %d is paid off", apperrors.ErrConflict, loanID)
+	}
+
+	fee := &Fee{LoanID: loanID, Type: feeType, Amount: amount, Reason: reason, PostedBy: optional(postedBy), PostedAt: s.clock.Now(), Waivers: []FeeWaiver{}}
+	fee.Tax = s.taxes.feeTax(fee, s.payments.Round)
+	if err := s.repo.PostFee(ctx, fee); err != nil {
+		if errors.Is(err, apperrors.ErrNotFound) {
+			return nil, err
+		}
+		s.logger.Error("Failed to post fee", "loanID", loanID, "error", err)
+		return nil, fmt.Errorf("%w: failed to post fee on loan %d: %v", apperrors.ErrInternalServer, loanID, err)
+	}
+	s.logger.Info("Fee posted", "loanID", loanID, "feeID", fee.ID, "type", fee.Type, "amount", fee.Amount, "postedBy", postedBy)
+	return fee, nil
+}
+
+func (s *loanServiceImpl) ChargeLateFees(ctx context.Context, loanID int64, policy DelinquencyPolicy) ([]Fee, error) {
+	if err := denyCustomerScope(ctx); err != nil {
+		return nil, err
+	}
+	if policy.LateFee <= 0 {
+		return nil, nil
+	}
+	l, err := s.loanFor(ctx, loanID, "late fee")
+	if err != nil {
+		return nil, err
+	}
+	if l.Status != StatusActive {
+		return nil, nil
+	}
+	if err := s.refuseHold(ctx, s.repo, loanID); err != nil {
+		return nil, err
+	}
+	schedule, err := s.repo.GetScheduleByLoanID(ctx, loanID)
+	if err != nil {
+		s.logger.Error("Failed to read schedule", "loanID", loanID, "error", err)
+		return nil, fmt.Errorf("%w: could not read schedule: %v", apperrors.ErrInternalServer, err)
+	}
+	now := s.clock.Now()
+	late := policy.LateInstallments(schedule, now)
+	if len(late) == 0 {
+		return nil, nil
+	}
+	fees, err := s.repo.GetFees(ctx, loanID)
+	if err != nil {
+		s.logger.Error("Failed to get loan fees", "loanID", loanID, "error", err)
+		return nil, fmt.Errorf("%w: failed to get fees of loan %d: %v", apperrors.ErrInternalServer, loanID, err)
+	}
+	// A late fee names its installment, which is how a later run knows it
+	// was charged.
+	charged := make(map[string]bool)
+	for _, f := range fees {
+		if f.Type == FeeLate {
+			charged[f.Reason] = true
+		}
+	}
+
+	var posted []Fee
+	for _, entry := range late {
+		reason := lateFeeReason(entry)
+		if charged[reason] {
+			continue
+		}
+		fee := &Fee{LoanID: loanID, Type: FeeLate, Amount: policy.LateFee, Reason: reason, PostedAt: now, Waivers: []FeeWaiver{}}
+		fee.Tax = s.taxes.feeTax(fee, s.payments.Round)
+		if err := s.repo.PostFee(ctx, fee); err != nil {
+			s.logger.Error("Failed to post late fee", "loanID", loanID, "week", entry.WeekNumber, "error", err)
+			return posted, fmt.Errorf("%w: failed to post late fee on loan %d: %v", apperrors.ErrInternalServer, loanID, err)
+		}
+		posted = append(posted, *fee)
+	}
+	if len(posted) > 0 {
+		s.logger.Info("Late fees posted", "loanID", loanID, "count", len(posted), "amount", policy.LateFee)
+	}
+	return posted, nil
+}
+
+// lateFeeReason names the installment a late fee is charged for.
+func lateFeeReason(entry ScheduleEntry) string {
+	return fmt.Sprintf("Installment %d due %s unpaid after the grace period", entry.WeekNumber, entry.DueDate.Format(time.DateOnly))
+}
+
+func (s *loanServiceImpl) GetFees(ctx context.Context, loanID int64) ([]Fee, error) {
+	if err := s.authorizeLoanAccess(ctx, loanID); err != nil {
+		return nil, err
+	}
+	if _, err := s.loanFor(ctx, loanID, "fee listing"); err != nil {
+		return nil, err
+	}
+	fees, err := s.repo.GetFees(ctx, loanID)
+	if err != nil {
+		s.logger.Error("Failed to get loan fees", "loanID", loanID, "error", err)
+		return nil, fmt.Errorf("%w: failed to get fees of loan %d: %v", apperrors.ErrInternalServer, loanID, err)
+	}
+	if _, scoped := scope.CustomerFromContext(ctx); scoped {
+		for i := range fees {
+			fees[i].PostedBy = nil
+			for j := range fees[i].Waivers {
+				fees[i].Waivers[j].RequestedBy, fees[i].Waivers[j].DecidedBy = nil, nil
+			}
+		}
+	}
+	return fees, nil
+}
+
+func (s *loanServiceImpl) ListPayments(ctx context.Context, loanID int64, limit int) ([]Payment, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("%w: limit must be positive", apperrors.ErrInvalidArgument)
+	}
+	if err := s.authorizeLoanAccess(ctx, loanID); err != nil {
+		return nil, err
+	}
+	if _, err := s.loanFor(ctx, loanID, "payment listing"); err != nil {
+		return nil, err
+	}
+	payments, err := s.repo.ListPayments(ctx, loanID, limit)
+	if err != nil {
+		s.logger.Error("Failed to list loan payments", "loanID", loanID, "error", err)
+		return nil, fmt.Errorf("%w: failed to list payments of loan %d: %v", apperrors.ErrInternalServer, loanID, err)
+	}
+	if _, scoped := scope.CustomerFromContext(ctx); scoped {
+		for i := range payments {
+			payments[i].CollectorID = nil
+		}
+	}
+	return payments, nil
+}
+
+func (s *loanServiceImpl) SearchLoans(ctx context.Context, q LoanSearch) ([]SearchMatch, error) {
+	if err := denyCustomerScope(ctx); err != nil {
+		return nil, err
+	}
+	q, err := q.normalize()
+	if err != nil {
+		return nil, err
+	}
+	matches, err := s.repo.SearchLoans(ctx, q)
+	if err != nil {
+		s.logger.Error("Failed to search loans", "error", err)
+		return nil, fmt.Errorf("%w: failed to search loans: %v", apperrors.ErrInternalServer, err)
+	}
+	return matches, nil
+}
+
+// feeOf finds fee feeID among the loan's fees.
+func (s *loanServiceImpl) feeOf(ctx context.Context, loanID, feeID int64) (*Fee, error) {
+	if err := s.authorizeTenant(ctx, loanID); err != nil {
+		return nil, err
+	}
+	fees, err := s.repo.GetFees(ctx, loanID)
+	if err != nil {
+		s.logger.Error("Failed to get loan fees", "loanID", loanID, "error", err)
+		return nil, fmt.Errorf("%w: failed to get fees of loan %d: %v", apperrors.ErrInternalServer, loanID, err)
+	}
+	for i := range fees {
+		if fees[i].ID == feeID {
+			return &fees[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: loan %d has no fee %d", apperrors.ErrNotFound, loanID, feeID)
+}
+
+func (s *loanServiceImpl) RequestFeeWaiver(ctx context.Context, loanID, feeID int64, reason, requestedBy string) (*FeeWaiver, error) {
+	if err := denyCustomerScope(ctx); err != nil {
+		return nil, err
+	}
+	if requestedBy == "" {
+		return nil, fmt.Errorf("%w: a waiver needs a named requester", apperrors.ErrForbidden)
+	}
+	reason, err := validateFeeReason(reason, "a waiver")
+	if err != nil {
+		return nil, err
+	}
+	fee, err := s.feeOf(ctx, loanID, feeID)
+	if err != nil {
+		return nil, err
+	}
+	if fee.Waived() {
+		return nil, fmt.Errorf("%w: fee %d is already waived", apperrors.ErrConflict, feeID)
+	}
+	if fee.PaidAt != nil {
+		return nil, fmt.Errorf("%w: fee %d is already paid", apperrors.ErrConflict, feeID)
+	}
+	if fee.PendingWaiver() != nil {
+		return nil, fmt.Errorf("%w: fee %d already has a waiver awaiting a decision", apperrors.ErrConflict, feeID)
+	}
+
+	waiver := &FeeWaiver{FeeID: feeID, LoanID: loanID, Reason: reason, Status: WaiverPending, RequestedBy: optional(requestedBy), RequestedAt: s.clock.Now()}
+	if err := s.repo.RequestFeeWaiver(ctx, waiver); err != nil {
+		if errors.Is(err, apperrors.ErrNotFound) || errors.Is(err, apperrors.ErrConflict) {
+			return nil, err
+		}
+		s.logger.Error("Failed to request fee waiver", "loanID", loanID, "feeID", feeID, "error", err)
+		return nil, fmt.Errorf("%w: failed to request waiver of fee %d: %v", apperrors.ErrInternalServer, feeID, err)
+	}
+	s.logger.Info("Fee waiver requested", "loanID", loanID, "feeID", feeID, "waiverID", waiver.ID, "requestedBy", requestedBy)
+	return waiver, nil
+}
+
+func (s *loanServiceImpl) DecideFeeWaiver(ctx context.Context, loanID, feeID int64, approve bool, decidedBy string) (*FeeWaiver, error) {
+	if err := denyCustomerScope(ctx); err != nil {
+		return nil, err
+	}
+	fee, err := s.feeOf(ctx, loanID, feeID)
+	if err != nil {
+		return nil, err
+	}
+	waiver := fee.PendingWaiver()
+	if waiver == nil {
+		return nil, fmt.Errorf("%w: fee %d has no waiver awaiting a decision", apperrors.ErrNotFound, feeID)
+	}
+	if err := checkWaiverDecision(waiver, decidedBy); err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	waiver.Status = WaiverRejected
+	if approve {
+		waiver.Status = WaiverApproved
+	}
+	waiver.DecidedBy = optional(decidedBy)
+	waiver.DecidedAt = &now
+	if err := s.repo.DecideFeeWaiver(ctx, waiver); err != nil {
+		if errors.Is(err, apperrors.ErrConflict) {
+			return nil, err
+		}
+		s.logger.Error("Failed to decide fee waiver", "loanID", loanID, "feeID", feeID, "error", err)
+		return nil, fmt.Errorf("%w: failed to decide waiver of fee %d: %v", apperrors.ErrInternalServer, feeID, err)
+	}
+	s.logger.Info("Fee waiver decided", "loanID", loanID, "feeID", feeID, "waiverID", waiver.ID, "status", waiver.Status, "decidedBy", decidedBy)
+
+	// Waiving the last thing owed pays the loan off as a payment would.
+	if approve {
+		err := s.repo.WithinTransaction(ctx, func(tx TxRepository) error {
+			return s.closeIfSettled(ctx, tx, loanID)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return waiver, nil
+}
+
+// GetLoanByExternalRef resolves the integrator's reference to a loan and then
+// loads it through GetLoan, so scope checks and schedule loading stay in one
+// place.
+func (s *loanServiceImpl) GetLoanByExternalRef(ctx context.Context, externalRef string, includeSchedule bool) (*Loan, error) {
+	s.logger.Info("Getting loan by external reference")
+	externalRef = strings.TrimSpace(externalRef)
+	if externalRef == "" {
+		return nil, fmt.Errorf("%w: external reference cannot be empty", apperrors.ErrInvalidArgument)
+	}
+	found, err := s.repo.GetLoanByExternalRef(ctx, externalRef)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) || errors.Is(err, apperrors.ErrNotFound) {
+			s.logger.Warn("Loan not found for external reference")
+			return nil, fmt.Errorf("%w: loan with external reference %q not found", apperrors.ErrNotFound, externalRef)
+		}
+		s.logger.Error("Failed to get loan by external reference", "error", err)
+		return nil, fmt.Errorf("%w: failed to get loan by external reference: %v", apperrors.ErrInternalServer, err)
+	}
+	return s.GetLoan(ctx, found.ID, includeSchedule)
+}
+
+// ResolveLoanID maps a public UUID from a URL to the internal loan ID.
+func (s *loanServiceImpl) ResolveLoanID(ctx context.Context, publicID uuid.UUID) (int64, error) {
+	found, err := s.repo.GetLoanByPublicID(ctx, publicID)
+	if err != nil {
+		if errors.Is(err, apperrors.ErrNotFound) {
+			return 0, fmt.Errorf("%w: loan with public ID %s not found", apperrors.ErrNotFound, publicID)
+		}
+		s.logger.Error("Failed to resolve loan public ID", "error", err)
+		return 0, fmt.Errorf("%w: failed to resolve loan public ID: %v", apperrors.ErrInternalServer, err)
+	}
+	return found.ID, nil
+}
+
 func (s *loanServiceImpl) GetLoanSchedule(ctx context.Context, loanID int64) ([]ScheduleEntry, error) {
+	if err := s.authorizeLoanAccess(ctx, loanID); err != nil {
+		return nil, err
+	}
+	return s.loanSchedule(ctx, loanID)
+}
+
+func (s *loanServiceImpl) loanSchedule(ctx context.Context, loanID int64) ([]ScheduleEntry, error) {
 	s.logger.Info("Getting loan schedule", "loanID", loanID)
 	schedule, err := s.repo.GetScheduleByLoanID(ctx, loanID)
 	if err != nil {
@@ -2,15 +2,23 @@ package loan
 
 import (
 	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/infrastructure/monitoring"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"billing-engine/internal/pkg/scope"
 	"context"
+	"errors"
 	"io"
 	"log/slog"
+	"strings"
 	"testing"
 	"time"
 
-	"github.com/jackc/pgx/v5"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 var logger = slog.New(slog.NewTextHandler(io.Discard, nil))
@@ -19,12 +27,12 @@ type MockCustomerService struct {
 	mock.Mock
 }
 
-func (_m *MockCustomerService) CreateNewCustomer(ctx context.Context, name string, address string) (*customer.Customer, error) {
-	ret := _m.Called(ctx, name, address)
+func (_m *MockCustomerService) CreateNewCustomer(ctx context.Context, name string, address string, externalRef string, publicID uuid.UUID, force bool) (*customer.Customer, error) {
+	ret := _m.Called(ctx, name, address, externalRef, publicID, force)
 
 	var r0 *customer.Customer
-	if rf, ok := ret.Get(0).(func(context.Context, string, string) *customer.Customer); ok {
-		r0 = rf(ctx, name, address)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, uuid.UUID, bool) *customer.Customer); ok {
+		r0 = rf(ctx, name, address, externalRef, publicID, force)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*customer.Customer)
@@ -32,8 +40,8 @@ func (_m *MockCustomerService) CreateNewCustomer(ctx context.Context, name strin
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
-		r1 = rf(ctx, name, address)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string, uuid.UUID, bool) error); ok {
+		r1 = rf(ctx, name, address, externalRef, publicID, force)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -41,6 +49,61 @@ func (_m *MockCustomerService) CreateNewCustomer(ctx context.Context, name strin
 	return r0, r1
 }
 
+func (_m *MockCustomerService) FindCustomerByExternalRef(ctx context.Context, externalRef string) (*customer.Customer, error) {
+	ret := _m.Called(ctx, externalRef)
+
+	var r0 *customer.Customer
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*customer.Customer)
+	}
+	return r0, ret.Error(1)
+}
+
+func (_m *MockCustomerService) ResolveCustomerID(ctx context.Context, publicID uuid.UUID) (int64, error) {
+	ret := _m.Called(ctx, publicID)
+	return ret.Get(0).(int64), ret.Error(1)
+}
+
+func (_m *MockCustomerService) GetPreferences(ctx context.Context, customerID int64) (*customer.Preferences, error) {
+	ret := _m.Called(ctx, customerID)
+	var r0 *customer.Preferences
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*customer.Preferences)
+	}
+	return r0, ret.Error(1)
+}
+
+func (_m *MockCustomerService) UpdatePreferences(ctx context.Context, p customer.Preferences) (*customer.Preferences, error) {
+	ret := _m.Called(ctx, p)
+	var r0 *customer.Preferences
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*customer.Preferences)
+	}
+	return r0, ret.Error(1)
+}
+
+func (_m *MockCustomerService) RequestRiskScore(ctx context.Context, cust *customer.Customer, reason customer.RiskScoreReason, principal float64) {
+	_m.Called(ctx, cust, reason, principal)
+}
+
+func (_m *MockCustomerService) UpdateRiskScore(ctx context.Context, a customer.RiskAssessment) (*customer.Customer, error) {
+	ret := _m.Called(ctx, a)
+	var r0 *customer.Customer
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*customer.Customer)
+	}
+	return r0, ret.Error(1)
+}
+
+func (_m *MockCustomerService) MergeCustomers(ctx context.Context, targetID, sourceID int64) (*customer.Merge, error) {
+	ret := _m.Called(ctx, targetID, sourceID)
+	var r0 *customer.Merge
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*customer.Merge)
+	}
+	return r0, ret.Error(1)
+}
+
 func (_m *MockCustomerService) GetCustomer(ctx context.Context, customerID int64) (*customer.Customer, error) {
 	ret := _m.Called(ctx, customerID)
 
@@ -85,6 +148,16 @@ func (_m *MockCustomerService) ListActiveCustomers(ctx context.Context) ([]*cust
 	return r0, r1
 }
 
+func (_m *MockCustomerService) ListActiveCustomersPage(ctx context.Context, afterID int64, limit int) ([]*customer.Customer, error) {
+	ret := _m.Called(ctx, afterID, limit)
+
+	var r0 []*customer.Customer
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*customer.Customer)
+	}
+	return r0, ret.Error(1)
+}
+
 func (_m *MockCustomerService) UpdateCustomerAddress(ctx context.Context, customerID int64, newAddress string) error {
 	ret := _m.Called(ctx, customerID, newAddress)
 
@@ -124,6 +197,33 @@ func (_m *MockCustomerService) UpdateDelinquency(ctx context.Context, customerID
 	return r0
 }
 
+func (_m *MockCustomerService) UpdateDelinquencyBulk(ctx context.Context, updates []customer.CustomerDelinquency) ([]*customer.Customer, error) {
+	ret := _m.Called(ctx, updates)
+
+	var r0 []*customer.Customer
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*customer.Customer)
+	}
+
+	return r0, ret.Error(1)
+}
+
+func (_m *MockCustomerService) NotifyDelinquency(ctx context.Context, notices []customer.DelinquencyNotice) ([]customer.DelinquencyNotice, error) {
+	ret := _m.Called(ctx, notices)
+
+	var r0 []customer.DelinquencyNotice
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]customer.DelinquencyNotice)
+	}
+
+	return r0, ret.Error(1)
+}
+
+func (_m *MockCustomerService) ClearDelinquencyNotices(ctx context.Context, customerIDs []int64) error {
+	ret := _m.Called(ctx, customerIDs)
+	return ret.Error(0)
+}
+
 func (_m *MockCustomerService) DeactivateCustomer(ctx context.Context, customerID int64) error {
 	ret := _m.Called(ctx, customerID)
 
@@ -175,7 +275,7 @@ func (_m *MockCustomerService) FindCustomerByLoan(ctx context.Context, loanID in
 func TestCreateLoan(t *testing.T) {
 	mockRepo := new(MockRepository)
 	mockCustomerService := new(MockCustomerService)
-	service := NewLoanService(mockRepo, mockCustomerService, logger)
+	service := NewLoanService(mockRepo, mockCustomerService, DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
 
 	ctx := context.Background()
 	principal := Money(1000)
@@ -187,105 +287,1251 @@ func TestCreateLoan(t *testing.T) {
 	loan := &Loan{}
 	mockRepo.On("CreateLoan", ctx, mock.Anything, mock.Anything, mock.Anything).Return(loan, nil)
 	mockCustomerService.On("GetCustomer", ctx, customerID).Return(&customer.Customer{CustomerID: customerID, Active: true}, nil)
-	mockCustomerService.On("AssignLoanToCustomer", ctx, customerID, mock.Anything).Return(nil)
+	mockCustomerService.On("RequestRiskScore", ctx, mock.Anything, customer.RiskScoreLoanApplication, principal).Return().Once()
 
-	result, err := service.CreateLoan(ctx, customerID, principal, termWeeks, annualInterestRate, startDate)
+	result, err := service.CreateLoan(ctx, customerID, principal, termWeeks, annualInterestRate, startDate, "", uuid.Nil, "")
 
 	assert.NoError(t, err)
 	assert.Equal(t, loan, result)
 	mockRepo.AssertExpectations(t)
+	mockCustomerService.AssertExpectations(t)
+	mockCustomerService.AssertNotCalled(t, "AssignLoanToCustomer", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCreateLoanFailurePoints(t *testing.T) {
+	ctx := context.Background()
+	customerID := int64(1)
+	paidOffID := int64(7)
+	newService := func(t *testing.T, cust *customer.Customer, custErr error) (LoanService, *MockRepository, *MockCustomerService) {
+		mockRepo := new(MockRepository)
+		mockCustomerService := new(MockCustomerService)
+		mockCustomerService.On("GetCustomer", ctx, customerID).Return(cust, custErr)
+		mockCustomerService.On("RequestRiskScore", ctx, cust, customer.RiskScoreLoanApplication, mock.Anything).Return().Maybe()
+		t.Cleanup(func() {
+			mockCustomerService.AssertNotCalled(t, "AssignLoanToCustomer", mock.Anything, mock.Anything, mock.Anything)
+		})
+		return NewLoanService(mockRepo, mockCustomerService, DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger), mockRepo, mockCustomerService
+	}
+	create := func(service LoanService) (*Loan, error) {
+		return service.CreateLoan(ctx, customerID, Money(1000), 4, Money(5), time.Now(), "", uuid.Nil, "")
+	}
+
+	t.Run("unknown customer", func(t *testing.T) {
+		service, mockRepo, _ := newService(t, nil, customer.ErrNotFound)
+
+		_, err := create(service)
+
+		assert.ErrorIs(t, err, apperrors.ErrValidation)
+		mockRepo.AssertNotCalled(t, "CreateLoan", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("customer with an active loan", func(t *testing.T) {
+		service, mockRepo, _ := newService(t, &customer.Customer{CustomerID: customerID, Active: true, LoanID: &paidOffID}, nil)
+		mockRepo.On("GetLoanByID", ctx, paidOffID).Return(&Loan{ID: paidOffID, Status: StatusActive}, nil)
+		mockRepo.On("GetActiveHold", ctx, paidOffID).Return((*Hold)(nil), nil)
+		mockRepo.On("GetRateHistory", ctx, paidOffID).Return([]RateChange{}, nil)
+		mockRepo.On("GetScheduleAdjustments", ctx, paidOffID).Return([]ScheduleAdjustment{}, nil)
+		mockRepo.On("GetPrepayments", ctx, paidOffID).Return([]Prepayment{}, nil)
+
+		_, err := create(service)
+
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+		assert.ErrorIs(t, err, customer.ErrCustomerAlreadyHasLoan)
+		mockRepo.AssertNotCalled(t, "CreateLoan", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("customer whose loan is paid off", func(t *testing.T) {
+		service, mockRepo, _ := newService(t, &customer.Customer{CustomerID: customerID, Active: true, LoanID: &paidOffID}, nil)
+		mockRepo.On("GetLoanByID", ctx, paidOffID).Return(&Loan{ID: paidOffID, Status: StatusPaidOff}, nil)
+		mockRepo.On("GetActiveHold", ctx, paidOffID).Return((*Hold)(nil), nil)
+		mockRepo.On("GetRateHistory", ctx, paidOffID).Return([]RateChange{}, nil)
+		mockRepo.On("GetScheduleAdjustments", ctx, paidOffID).Return([]ScheduleAdjustment{}, nil)
+		mockRepo.On("GetPrepayments", ctx, paidOffID).Return([]Prepayment{}, nil)
+		mockRepo.On("CreateLoan", ctx, customerID, mock.Anything, mock.Anything).Return(&Loan{ID: 8}, nil)
+
+		created, err := create(service)
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(8), created.ID)
+	})
+
+	t.Run("customer changed before the link", func(t *testing.T) {
+		service, mockRepo, _ := newService(t, &customer.Customer{CustomerID: customerID, Active: true}, nil)
+		mockRepo.On("CreateLoan", ctx, customerID, mock.Anything, mock.Anything).
+			Return((*Loan)(nil), apperrors.ErrConflict)
+
+		created, err := create(service)
+
+		assert.Nil(t, created)
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+		assert.NotErrorIs(t, err, apperrors.ErrInternalServer)
+	})
+
+	t.Run("store fails", func(t *testing.T) {
+		service, mockRepo, _ := newService(t, &customer.Customer{CustomerID: customerID, Active: true}, nil)
+		mockRepo.On("CreateLoan", ctx, customerID, mock.Anything, mock.Anything).
+			Return((*Loan)(nil), apperrors.ErrDatabase)
+
+		created, err := create(service)
+
+		assert.Nil(t, created)
+		assert.ErrorIs(t, err, apperrors.ErrInternalServer)
+	})
+}
+
+func TestCreateLoanDefaultsStartDateToToday(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockCustomerService := new(MockCustomerService)
+	now := time.Date(2025, 3, 14, 15, 9, 26, 0, time.UTC)
+	service := NewLoanService(mockRepo, mockCustomerService, DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.NewFake(now), logger)
+
+	ctx := context.Background()
+	customerID := int64(1)
+	mockCustomerService.On("GetCustomer", ctx, customerID).Return(&customer.Customer{CustomerID: customerID, Active: true}, nil)
+	mockCustomerService.On("RequestRiskScore", ctx, mock.Anything, customer.RiskScoreLoanApplication, mock.Anything).Return()
+	mockRepo.On("CreateLoan", ctx, customerID, mock.MatchedBy(func(l *Loan) bool {
+		return l.StartDate.Equal(time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC))
+	}), mock.MatchedBy(func(schedule []ScheduleEntry) bool {
+		return len(schedule) == 4 && schedule[0].DueDate.Equal(time.Date(2025, 3, 21, 0, 0, 0, 0, time.UTC))
+	})).Return(&Loan{}, nil)
+
+	_, err := service.CreateLoan(ctx, customerID, Money(1000), 4, Money(0.1), time.Time{}, "", uuid.Nil, "")
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
 }
 
 func TestGetOutstanding(t *testing.T) {
 	mockRepo := new(MockRepository)
 
 	mockCustomerService := new(MockCustomerService)
-	service := NewLoanService(mockRepo, mockCustomerService, logger)
+	service := NewLoanService(mockRepo, mockCustomerService, DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
 
 	ctx := context.Background()
 	loanID := int64(1)
-	expectedOutstanding := Money(500)
-
-	mockRepo.On("GetTotalOutstandingAmount", ctx, loanID).Return(expectedOutstanding, nil)
+	expectOutstandingLookups(mockRepo, ctx, loanID, []BalanceItem{{Kind: BalanceSuspense, Amount: 110}})
 
 	result, err := service.GetOutstanding(ctx, loanID)
 
 	assert.NoError(t, err)
-	assert.Equal(t, expectedOutstanding, result)
+	assert.Equal(t, Money(110), result, "two unpaid installments less the suspense")
 	mockRepo.AssertExpectations(t)
 }
 
-func TestIsDelinquent(t *testing.T) {
+// expectOutstandingLookups serves a 300 loan repaid in three installments of
+// 110, the first of them paid.
+func expectOutstandingLookups(mockRepo *MockRepository, ctx context.Context, loanID int64, items []BalanceItem) {
+	start := time.Now().AddDate(0, 0, -1)
+	mockRepo.On("GetLoanByID", ctx, loanID).Return(&Loan{ID: loanID, PrincipalAmount: 300, TotalLoanAmount: 330}, nil)
+	mockRepo.On("GetScheduleByLoanID", ctx, loanID).Return([]ScheduleEntry{
+		{WeekNumber: 1, DueDate: start.AddDate(0, 0, 7), DueAmount: 110, PaidAmount: 110, Status: PaymentStatusPaid},
+		{WeekNumber: 2, DueDate: start.AddDate(0, 0, 14), DueAmount: 110, Status: PaymentStatusPending},
+		{WeekNumber: 3, DueDate: start.AddDate(0, 0, 21), DueAmount: 110, Status: PaymentStatusPending},
+	}, nil)
+	mockRepo.On("ListBalanceItems", ctx, loanID).Return(items, nil)
+	mockRepo.On("GetPrepayments", ctx, loanID).Return([]Prepayment{}, nil)
+}
+
+func TestGetOutstandingBreakdownLoanNotFound(t *testing.T) {
 	mockRepo := new(MockRepository)
+	service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
+	ctx := context.Background()
+	mockRepo.On("GetLoanByID", ctx, int64(7)).Return((*Loan)(nil), apperrors.ErrNotFound)
 
-	mockCustomerService := new(MockCustomerService)
-	service := NewLoanService(mockRepo, mockCustomerService, logger)
+	_, err := service.GetOutstandingBreakdown(ctx, 7)
 
-	ctx := context.Background()
-	loanID := int64(1)
-	lastTwoUnpaid := []ScheduleEntry{{}, {}}
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	mockRepo.AssertNotCalled(t, "ListBalanceItems", mock.Anything, mock.Anything)
+}
 
-	mockRepo.On("GetLastTwoDueUnpaidSchedules", ctx, loanID).Return(lastTwoUnpaid, nil)
+func TestGetLastModified(t *testing.T) {
+	mockRepo := new(MockRepository)
+	service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
+	ctx := context.Background()
+	lastModified := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
 
-	result, err := service.IsDelinquent(ctx, loanID)
+	mockRepo.On("GetLastModified", ctx, int64(1)).Return(lastModified, nil)
+	mockRepo.On("GetLastModified", ctx, int64(2)).Return(time.Time{}, apperrors.ErrNotFound)
 
+	result, err := service.GetLastModified(ctx, 1)
 	assert.NoError(t, err)
-	assert.True(t, result)
+	assert.Equal(t, lastModified, result)
+
+	_, err = service.GetLastModified(ctx, 2)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
 	mockRepo.AssertExpectations(t)
 }
 
-func TestMakePayment(t *testing.T) {
-	type TxMock struct {
-		pgx.Tx
+func TestStreamLoans(t *testing.T) {
+	t.Run("passes every loan after the cursor to the callback", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
+		ctx := context.Background()
+		mockRepo.On("StreamLoans", ctx, LoanFilter{AfterID: 10}).Return([]*Loan{{ID: 11}, {ID: 12}}, nil)
+
+		var ids []int64
+		err := service.StreamLoans(ctx, LoanFilter{AfterID: 10}, func(l *Loan) error {
+			ids = append(ids, l.ID)
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{11, 12}, ids)
+	})
+
+	t.Run("keeps the callback error", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
+		ctx := context.Background()
+		mockRepo.On("StreamLoans", ctx, LoanFilter{}).Return([]*Loan{{ID: 1}}, nil)
+		clientGone := errors.New("client went away")
+
+		err := service.StreamLoans(ctx, LoanFilter{}, func(*Loan) error { return clientGone })
+
+		assert.ErrorIs(t, err, clientGone)
+	})
+
+	t.Run("rejects a negative cursor", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
+
+		err := service.StreamLoans(context.Background(), LoanFilter{AfterID: -1}, func(*Loan) error { return nil })
+
+		assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+		mockRepo.AssertNotCalled(t, "StreamLoans", mock.Anything, mock.Anything)
+	})
+
+	t.Run("rejects a negative days past due filter", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
+
+		err := service.StreamLoans(context.Background(), LoanFilter{MinDaysPastDue: -5}, func(*Loan) error { return nil })
+
+		assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+		mockRepo.AssertNotCalled(t, "StreamLoans", mock.Anything, mock.Anything)
+	})
+
+	t.Run("forbids customer scoped callers", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
+		ctx := scope.WithCustomer(context.Background(), 42)
+
+		err := service.StreamLoans(ctx, LoanFilter{}, func(*Loan) error { return nil })
+
+		assert.ErrorIs(t, err, apperrors.ErrForbidden)
+		mockRepo.AssertNotCalled(t, "StreamLoans", mock.Anything, mock.Anything)
+	})
+}
+
+func TestIsDelinquent(t *testing.T) {
+	now := time.Date(2025, 2, 3, 10, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+	loanID := int64(1)
+	// Three installments are past due on now; the fourth is due today.
+	schedule := func(paid int) []ScheduleEntry {
+		entries := make([]ScheduleEntry, 4)
+		for i := range entries {
+			due := time.Date(2025, 1, 13, 0, 0, 0, 0, time.UTC).AddDate(0, 0, 7*i)
+			entries[i] = ScheduleEntry{WeekNumber: i + 1, DueDate: due, DueAmount: 110, Status: PaymentStatusPending}
+			if i < paid {
+				entries[i].Status = PaymentStatusPaid
+				entries[i].PaymentDate = &due
+			}
+		}
+		return entries
+	}
+
+	tests := []struct {
+		name     string
+		schedule []ScheduleEntry
+		customer *customer.Customer
+		custErr  error
+		want     bool
+	}{
+		{name: "two missed payments flag the customer", schedule: schedule(1), customer: &customer.Customer{}, want: true},
+		{name: "one missed payment does not", schedule: schedule(2), customer: &customer.Customer{}, want: false},
+		{name: "installment due today is not missed", schedule: schedule(3), customer: &customer.Customer{}, want: false},
+		{name: "flagged customer stays flagged while behind", schedule: schedule(2), customer: &customer.Customer{IsDelinquent: true}, want: true},
+		{name: "flagged customer is cured by catching up", schedule: schedule(3), customer: &customer.Customer{IsDelinquent: true}, want: false},
+		{name: "customer without record is evaluated unflagged", schedule: schedule(2), custErr: customer.ErrNotFound, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			mockCustomerService := new(MockCustomerService)
+			service := NewLoanService(mockRepo, mockCustomerService, DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.NewFake(now), logger)
+
+			mockRepo.On("GetScheduleByLoanID", ctx, loanID).Return(tt.schedule, nil)
+			mockCustomerService.On("FindCustomerByLoan", ctx, loanID).Return(tt.customer, tt.custErr)
+
+			result, err := service.IsDelinquent(ctx, loanID)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, result)
+			mockRepo.AssertExpectations(t)
+			mockCustomerService.AssertExpectations(t)
+		})
 	}
+
+	t.Run("customer lookup failure", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockCustomerService := new(MockCustomerService)
+		service := NewLoanService(mockRepo, mockCustomerService, DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.NewFake(now), logger)
+
+		mockRepo.On("GetScheduleByLoanID", ctx, loanID).Return(schedule(0), nil)
+		mockCustomerService.On("FindCustomerByLoan", ctx, loanID).Return(nil, errors.New("connection reset"))
+
+		_, err := service.IsDelinquent(ctx, loanID)
+
+		assert.ErrorIs(t, err, apperrors.ErrInternalServer)
+	})
+}
+
+func TestMakePayment(t *testing.T) {
 	mockRepo := new(MockRepository)
 
 	mockCustomerService := new(MockCustomerService)
-	service := NewLoanService(mockRepo, mockCustomerService, logger)
+	paidAt := time.Date(2025, 2, 3, 10, 30, 0, 0, time.UTC)
+	service := NewLoanService(mockRepo, mockCustomerService, DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.NewFake(paidAt), logger)
 
 	ctx := context.Background()
 	loanID := int64(1)
 	amount := Money(100)
-	tx := &TxMock{}
+	tx := new(MockTxRepository)
 	entry := &ScheduleEntry{DueAmount: amount}
 
-	mockRepo.On("BeginTx", ctx).Return(tx, nil)
-	mockRepo.On("FindOldestUnpaidEntryForUpdate", ctx, tx, loanID).Return(entry, nil)
-	mockRepo.On("UpdateScheduleEntryInTx", ctx, tx, entry).Return(nil)
-	mockRepo.On("CheckIfAllPaymentsMadeInTx", ctx, tx, loanID).Return(false, nil)
-	mockRepo.On("CommitTx", ctx, tx).Return(nil)
+	mockRepo.On("WithinTransaction", ctx).Return(tx, nil)
+	tx.On("LockLoanForPayment", ctx, loanID).Return(nil)
+	tx.On("GetActiveHold", ctx, loanID).Return((*Hold)(nil), nil)
+	tx.On("FindOldestUnpaidEntryForUpdate", ctx, loanID).Return(entry, nil)
+	tx.On("UpdateScheduleEntry", ctx, entry).Return(nil)
+	var recorded *Payment
+	tx.On("RecordPayment", ctx, mock.AnythingOfType("*loan.Payment")).
+		Run(func(args mock.Arguments) { recorded = args.Get(1).(*Payment) }).Return(nil)
+	tx.On("CheckIfAllPaymentsMade", ctx, loanID).Return(false, nil)
+	receivedBefore := testutil.ToFloat64(monitoring.Business.PaymentsReceivedAmount.WithLabelValues(string(ChannelCash)))
 
-	err := service.MakePayment(ctx, loanID, amount)
+	err := service.MakePayment(ctx, loanID, amount, PaymentDetails{Channel: " cash", Reference: " RCPT-1 ", CollectorID: "agent-7"})
 
 	assert.NoError(t, err)
+	assert.Equal(t, receivedBefore+amount, testutil.ToFloat64(monitoring.Business.PaymentsReceivedAmount.WithLabelValues(string(ChannelCash))))
+	assert.Equal(t, PaymentStatusPaid, entry.Status)
+	if assert.NotNil(t, entry.PaymentDate) {
+		assert.Equal(t, paidAt, *entry.PaymentDate, "payments are stamped by the service clock")
+	}
+	require.NotNil(t, recorded)
+	assert.Equal(t, ChannelCash, recorded.Channel)
+	assert.Equal(t, "RCPT-1", *recorded.Reference)
+	assert.Equal(t, "agent-7", *recorded.CollectorID)
+	assert.Equal(t, amount, recorded.Amount)
+	assert.Equal(t, paidAt, recorded.PaidAt)
+	mockRepo.AssertExpectations(t)
+	tx.AssertExpectations(t)
+}
+
+func TestMakePaymentRecordsUnspecifiedChannel(t *testing.T) {
+	mockRepo := new(MockRepository)
+	service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
+
+	ctx := context.Background()
+	tx := new(MockTxRepository)
+	entry := &ScheduleEntry{ID: 3, DueAmount: 100}
+
+	mockRepo.On("WithinTransaction", ctx).Return(tx, nil)
+	tx.On("LockLoanForPayment", ctx, int64(1)).Return(nil)
+	tx.On("GetActiveHold", ctx, int64(1)).Return((*Hold)(nil), nil)
+	tx.On("FindOldestUnpaidEntryForUpdate", ctx, int64(1)).Return(entry, nil)
+	tx.On("UpdateScheduleEntry", ctx, entry).Return(nil)
+	tx.On("RecordPayment", ctx, mock.MatchedBy(func(p *Payment) bool {
+		return p.Channel == ChannelUnspecified && p.Reference == nil && p.CollectorID == nil && *p.ScheduleID == 3 && p.FeeID == nil
+	})).Return(nil)
+	tx.On("CheckIfAllPaymentsMade", ctx, int64(1)).Return(false, nil)
+
+	assert.NoError(t, service.MakePayment(ctx, 1, 100, PaymentDetails{}))
+	mockRepo.AssertExpectations(t)
+	tx.AssertExpectations(t)
+}
+
+func TestMakePaymentSettlesFeesBeforePayingOff(t *testing.T) {
+	ctx := context.Background()
+	paidAt := time.Date(2025, 2, 3, 10, 30, 0, 0, time.UTC)
+	newService := func() (LoanService, *MockRepository, *MockTxRepository) {
+		mockRepo := new(MockRepository)
+		tx := new(MockTxRepository)
+		mockRepo.On("WithinTransaction", ctx).Return(tx, nil)
+		tx.On("LockLoanForPayment", ctx, int64(1)).Return(nil)
+		tx.On("GetActiveHold", ctx, int64(1)).Return((*Hold)(nil), nil)
+		return NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.NewFake(paidAt), logger), mockRepo, tx
+	}
+	openFee := func() *Fee {
+		return &Fee{ID: 4, LoanID: 1, Type: FeeLegal, Amount: 25, Tax: &TaxLine{ID: 6, FeeID: 4, Amount: 2.75}}
+	}
+
+	t.Run("keeps the loan open after its last installment while a fee is owed", func(t *testing.T) {
+		service, mockRepo, tx := newService()
+		entry := &ScheduleEntry{ID: 50, DueAmount: 100}
+		tx.On("FindOldestUnpaidEntryForUpdate", ctx, int64(1)).Return(entry, nil)
+		tx.On("UpdateScheduleEntry", ctx, entry).Return(nil)
+		tx.On("RecordPayment", ctx, mock.Anything).Return(nil)
+		tx.On("CheckIfAllPaymentsMade", ctx, int64(1)).Return(false, nil)
+
+		require.NoError(t, service.MakePayment(ctx, 1, 100, PaymentDetails{}))
+
+		tx.AssertNotCalled(t, "UpdateLoanStatus", mock.Anything, mock.Anything, mock.Anything)
+		mockRepo.AssertExpectations(t)
+		tx.AssertExpectations(t)
+	})
+
+	t.Run("pays the oldest fee with its tax and then pays the loan off", func(t *testing.T) {
+		service, mockRepo, tx := newService()
+		fee := openFee()
+		tx.On("FindOldestUnpaidEntryForUpdate", ctx, int64(1)).Return((*ScheduleEntry)(nil), apperrors.ErrNotFound)
+		tx.On("FindOldestOpenFeeForUpdate", ctx, int64(1)).Return(fee, nil)
+		tx.On("SettleFee", ctx, fee).Return(nil)
+		var recorded *Payment
+		tx.On("RecordPayment", ctx, mock.AnythingOfType("*loan.Payment")).
+			Run(func(args mock.Arguments) { recorded = args.Get(1).(*Payment) }).Return(nil)
+		tx.On("CheckIfAllPaymentsMade", ctx, int64(1)).Return(true, nil)
+		tx.On("UpdateLoanStatus", ctx, int64(1), StatusPaidOff).Return(nil)
+
+		require.NoError(t, service.MakePayment(ctx, 1, 27.75, PaymentDetails{Channel: ChannelCash}))
+
+		if assert.NotNil(t, fee.PaidAt) {
+			assert.Equal(t, paidAt, *fee.PaidAt)
+		}
+		require.NotNil(t, recorded)
+		assert.Nil(t, recorded.ScheduleID)
+		assert.Equal(t, int64(4), *recorded.FeeID)
+		assert.Equal(t, Money(27.75), recorded.Amount)
+		mockRepo.AssertExpectations(t)
+		tx.AssertExpectations(t)
+	})
+
+	t.Run("refuses an amount that leaves the fee's tax unpaid", func(t *testing.T) {
+		service, _, tx := newService()
+		tx.On("FindOldestUnpaidEntryForUpdate", ctx, int64(1)).Return((*ScheduleEntry)(nil), apperrors.ErrNotFound)
+		tx.On("FindOldestOpenFeeForUpdate", ctx, int64(1)).Return(openFee(), nil)
+
+		err := service.MakePayment(ctx, 1, 25, PaymentDetails{})
+
+		var amountErr *apperrors.PaymentAmountError
+		require.ErrorAs(t, err, &amountErr)
+		assert.Equal(t, Money(27.75), amountErr.Expected)
+		tx.AssertNotCalled(t, "SettleFee", mock.Anything, mock.Anything)
+	})
+
+	t.Run("reports a loan with nothing left to pay", func(t *testing.T) {
+		service, _, tx := newService()
+		tx.On("FindOldestUnpaidEntryForUpdate", ctx, int64(1)).Return((*ScheduleEntry)(nil), apperrors.ErrNotFound)
+		tx.On("FindOldestOpenFeeForUpdate", ctx, int64(1)).Return((*Fee)(nil), apperrors.ErrNotFound)
+
+		err := service.MakePayment(ctx, 1, 100, PaymentDetails{})
+
+		assert.ErrorIs(t, err, apperrors.ErrLoanFullyPaid)
+	})
+}
+
+func TestMakePaymentRejectsDuplicateReference(t *testing.T) {
+	mockRepo := new(MockRepository)
+	service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
+
+	ctx := context.Background()
+	tx := new(MockTxRepository)
+	entry := &ScheduleEntry{DueAmount: 100}
+
+	mockRepo.On("WithinTransaction", ctx).Return(tx, nil)
+	tx.On("LockLoanForPayment", ctx, int64(1)).Return(nil)
+	tx.On("GetActiveHold", ctx, int64(1)).Return((*Hold)(nil), nil)
+	tx.On("FindOldestUnpaidEntryForUpdate", ctx, int64(1)).Return(entry, nil)
+	tx.On("UpdateScheduleEntry", ctx, entry).Return(nil)
+	tx.On("RecordPayment", ctx, mock.Anything).Return(apperrors.ErrAlreadyExists)
+
+	err := service.MakePayment(ctx, 1, 100, PaymentDetails{Channel: ChannelBankTransfer, Reference: "TRF-9"})
+
+	assert.ErrorIs(t, err, apperrors.ErrAlreadyExists)
+	assert.Contains(t, err.Error(), "TRF-9")
+	mockRepo.AssertExpectations(t)
+	tx.AssertExpectations(t)
+}
+
+func TestMakePaymentRejectsConcurrentPayment(t *testing.T) {
+	mockRepo := new(MockRepository)
+	service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
+
+	ctx := context.Background()
+	tx := new(MockTxRepository)
+	mockRepo.On("WithinTransaction", ctx).Return(tx, nil)
+	tx.On("LockLoanForPayment", ctx, int64(1)).Return(apperrors.ErrConflict)
+
+	err := service.MakePayment(ctx, 1, 100, PaymentDetails{})
+
+	assert.ErrorIs(t, err, apperrors.ErrConflict)
+	tx.AssertNotCalled(t, "FindOldestUnpaidEntryForUpdate", mock.Anything, mock.Anything)
+	mockRepo.AssertExpectations(t)
+	tx.AssertExpectations(t)
+}
+
+func TestMakePaymentRejectsLoanOnHold(t *testing.T) {
+	mockRepo := new(MockRepository)
+	service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
+
+	ctx := context.Background()
+	tx := new(MockTxRepository)
+	mockRepo.On("WithinTransaction", ctx).Return(tx, nil)
+	tx.On("LockLoanForPayment", ctx, int64(1)).Return(nil)
+	tx.On("GetActiveHold", ctx, int64(1)).Return(&Hold{ID: 4, LoanID: 1, Reason: "fraud review"}, nil)
+
+	err := service.MakePayment(ctx, 1, 100, PaymentDetails{})
+
+	assert.ErrorIs(t, err, apperrors.ErrLoanOnHold)
+	assert.Contains(t, err.Error(), "fraud review")
+	tx.AssertNotCalled(t, "FindOldestUnpaidEntryForUpdate", mock.Anything, mock.Anything)
+	mockRepo.AssertExpectations(t)
+	tx.AssertExpectations(t)
+}
+
+func TestPlaceHold(t *testing.T) {
+	placedAt := time.Date(2025, 4, 2, 9, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	t.Run("records the trimmed reason and who placed it", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.NewFake(placedAt), logger)
+		mockRepo.On("PlaceHold", ctx, mock.MatchedBy(func(h *Hold) bool {
+			return h.LoanID == 1 && h.Reason == "disputed" && *h.PlacedBy == "admin" && h.PlacedAt.Equal(placedAt)
+		})).Run(func(args mock.Arguments) { args.Get(1).(*Hold).ID = 9 }).Return(nil)
+
+		hold, err := service.PlaceHold(ctx, 1, "  disputed ", "admin")
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(9), hold.ID)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("passes on a hold already in place", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.NewFake(placedAt), logger)
+		mockRepo.On("PlaceHold", ctx, mock.Anything).Return(apperrors.ErrConflict)
+
+		_, err := service.PlaceHold(ctx, 1, "disputed", "admin")
+
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+	})
+
+	t.Run("rejects a blank reason", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.NewFake(placedAt), logger)
+
+		_, err := service.PlaceHold(ctx, 1, "   ", "admin")
+
+		assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+		mockRepo.AssertNotCalled(t, "PlaceHold", mock.Anything, mock.Anything)
+	})
+
+	t.Run("forbids customer scope", func(t *testing.T) {
+		service := NewLoanService(new(MockRepository), new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.NewFake(placedAt), logger)
+
+		_, err := service.PlaceHold(scope.WithCustomer(ctx, 5), 1, "disputed", "admin")
+
+		assert.ErrorIs(t, err, apperrors.ErrForbidden)
+	})
+}
+
+func TestReleaseHold(t *testing.T) {
+	releasedAt := time.Date(2025, 4, 3, 9, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.NewFake(releasedAt), logger)
+
+	mockRepo.On("ReleaseHold", ctx, int64(1), mock.MatchedBy(func(by *string) bool { return *by == "admin" }), releasedAt).
+		Return(&Hold{ID: 9, LoanID: 1, ReleasedAt: &releasedAt}, nil).Once()
+	hold, err := service.ReleaseHold(ctx, 1, "admin")
+	require.NoError(t, err)
+	assert.Equal(t, int64(9), hold.ID)
+
+	mockRepo.On("ReleaseHold", ctx, int64(2), mock.Anything, releasedAt).Return(nil, apperrors.ErrNotFound).Once()
+	_, err = service.ReleaseHold(ctx, 2, "admin")
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestRebuildSchedule(t *testing.T) {
+	ctx := context.Background()
+	newLoan := func(t *testing.T) (*Loan, []ScheduleEntry) {
+		l, err := NewLoan(300, 3, 0.1, time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC))
+		require.NoError(t, err)
+		l.ID = 1
+		schedule, err := l.GenerateSchedule()
+		require.NoError(t, err)
+		return l, schedule[:2]
+	}
+
+	t.Run("writes the changes", func(t *testing.T) {
+		l, stored := newLoan(t)
+		mockRepo := new(MockRepository)
+		tx := new(MockTxRepository)
+		service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
+		mockRepo.On("GetLoanByID", ctx, int64(1)).Return(l, nil)
+		mockRepo.On("WithinTransaction", ctx).Return(tx, nil)
+		tx.On("LockLoanForPayment", ctx, int64(1)).Return(nil)
+		tx.On("GetActiveHold", ctx, int64(1)).Return((*Hold)(nil), nil)
+		tx.On("GetScheduleForUpdate", ctx, int64(1)).Return(stored, nil)
+		tx.On("GetRateHistory", ctx, int64(1)).Return([]RateChange{}, nil)
+		tx.On("GetScheduleAdjustments", ctx, int64(1)).Return([]ScheduleAdjustment{}, nil)
+		tx.On("GetPrepayments", ctx, int64(1)).Return([]Prepayment{}, nil)
+		tx.On("ApplyScheduleChanges", ctx, int64(1), mock.MatchedBy(func(changes []ScheduleChange) bool {
+			return len(changes) == 1 && changes[0].Kind == ScheduleEntryAdded && changes[0].WeekNumber == 3
+		})).Return(nil)
+
+		rebuild, err := service.RebuildSchedule(ctx, 1, false)
+
+		require.NoError(t, err)
+		assert.False(t, rebuild.DryRun)
+		assert.Len(t, rebuild.Changes, 1)
+		tx.AssertNotCalled(t, "UpdateLoanStatus", mock.Anything, mock.Anything, mock.Anything)
+		mockRepo.AssertExpectations(t)
+		tx.AssertExpectations(t)
+	})
+
+	t.Run("writes nothing on a dry run", func(t *testing.T) {
+		l, stored := newLoan(t)
+		l.Status = StatusPaidOff
+		mockRepo := new(MockRepository)
+		tx := new(MockTxRepository)
+		service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
+		mockRepo.On("GetLoanByID", ctx, int64(1)).Return(l, nil)
+		mockRepo.On("WithinTransaction", ctx).Return(tx, nil)
+		tx.On("LockLoanForPayment", ctx, int64(1)).Return(nil)
+		tx.On("GetActiveHold", ctx, int64(1)).Return((*Hold)(nil), nil)
+		tx.On("GetScheduleForUpdate", ctx, int64(1)).Return(stored, nil)
+		tx.On("GetRateHistory", ctx, int64(1)).Return([]RateChange{}, nil)
+		tx.On("GetScheduleAdjustments", ctx, int64(1)).Return([]ScheduleAdjustment{}, nil)
+		tx.On("GetPrepayments", ctx, int64(1)).Return([]Prepayment{}, nil)
+
+		rebuild, err := service.RebuildSchedule(ctx, 1, true)
+
+		require.NoError(t, err)
+		assert.True(t, rebuild.DryRun)
+		assert.Len(t, rebuild.Changes, 1)
+		assert.Equal(t, StatusActive, rebuild.Status, "the status the loan would get")
+		tx.AssertNotCalled(t, "ApplyScheduleChanges", mock.Anything, mock.Anything, mock.Anything)
+		tx.AssertNotCalled(t, "UpdateLoanStatus", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("reopens a paid-off loan", func(t *testing.T) {
+		l, stored := newLoan(t)
+		l.Status = StatusPaidOff
+		mockRepo := new(MockRepository)
+		tx := new(MockTxRepository)
+		service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
+		mockRepo.On("GetLoanByID", ctx, int64(1)).Return(l, nil)
+		mockRepo.On("WithinTransaction", ctx).Return(tx, nil)
+		tx.On("LockLoanForPayment", ctx, int64(1)).Return(nil)
+		tx.On("GetActiveHold", ctx, int64(1)).Return((*Hold)(nil), nil)
+		tx.On("GetScheduleForUpdate", ctx, int64(1)).Return(stored, nil)
+		tx.On("GetRateHistory", ctx, int64(1)).Return([]RateChange{}, nil)
+		tx.On("GetScheduleAdjustments", ctx, int64(1)).Return([]ScheduleAdjustment{}, nil)
+		tx.On("GetPrepayments", ctx, int64(1)).Return([]Prepayment{}, nil)
+		tx.On("ApplyScheduleChanges", ctx, int64(1), mock.Anything).Return(nil)
+		tx.On("UpdateLoanStatus", ctx, int64(1), StatusActive).Return(nil)
+
+		_, err := service.RebuildSchedule(ctx, 1, false)
+
+		require.NoError(t, err)
+		tx.AssertExpectations(t)
+	})
+
+	t.Run("refuses a loan on hold", func(t *testing.T) {
+		l, _ := newLoan(t)
+		mockRepo := new(MockRepository)
+		tx := new(MockTxRepository)
+		service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
+		mockRepo.On("GetLoanByID", ctx, int64(1)).Return(l, nil)
+		mockRepo.On("WithinTransaction", ctx).Return(tx, nil)
+		tx.On("LockLoanForPayment", ctx, int64(1)).Return(nil)
+		tx.On("GetActiveHold", ctx, int64(1)).Return(&Hold{ID: 3, LoanID: 1, Reason: "disputed"}, nil)
+
+		_, err := service.RebuildSchedule(ctx, 1, false)
+
+		assert.ErrorIs(t, err, apperrors.ErrLoanOnHold)
+		tx.AssertNotCalled(t, "GetScheduleForUpdate", mock.Anything, mock.Anything)
+		tx.AssertNotCalled(t, "ApplyScheduleChanges", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("passes on a payment in progress", func(t *testing.T) {
+		l, _ := newLoan(t)
+		mockRepo := new(MockRepository)
+		tx := new(MockTxRepository)
+		service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
+		mockRepo.On("GetLoanByID", ctx, int64(1)).Return(l, nil)
+		mockRepo.On("WithinTransaction", ctx).Return(tx, nil)
+		tx.On("LockLoanForPayment", ctx, int64(1)).Return(apperrors.ErrConflict)
+
+		_, err := service.RebuildSchedule(ctx, 1, true)
+
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+		tx.AssertNotCalled(t, "GetScheduleForUpdate", mock.Anything, mock.Anything)
+	})
+
+	t.Run("reports an unknown loan", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
+		mockRepo.On("GetLoanByID", ctx, int64(1)).Return((*Loan)(nil), apperrors.ErrNotFound)
+
+		_, err := service.RebuildSchedule(ctx, 1, false)
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+		mockRepo.AssertNotCalled(t, "WithinTransaction", mock.Anything)
+	})
+
+	t.Run("forbids customer scope", func(t *testing.T) {
+		service := NewLoanService(new(MockRepository), new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
+
+		_, err := service.RebuildSchedule(scope.WithCustomer(ctx, 5), 1, true)
+
+		assert.ErrorIs(t, err, apperrors.ErrForbidden)
+	})
+}
+
+func TestRepriceLoan(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 15, 9, 0, 0, 0, time.UTC)
+	effectiveFrom := time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC)
+	newLoan := func(t *testing.T) (*Loan, []ScheduleEntry) {
+		l, err := NewLoan(300, 3, 0.1, time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC))
+		require.NoError(t, err)
+		l.ID = 1
+		schedule, err := l.GenerateSchedule()
+		require.NoError(t, err)
+		return l, schedule
+	}
+
+	t.Run("writes the schedule and the rate change", func(t *testing.T) {
+		l, stored := newLoan(t)
+		mockRepo := new(MockRepository)
+		tx := new(MockTxRepository)
+		service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.NewFake(now), logger)
+		mockRepo.On("GetLoanByID", ctx, int64(1)).Return(l, nil)
+		mockRepo.On("WithinTransaction", ctx).Return(tx, nil)
+		tx.On("LockLoanForPayment", ctx, int64(1)).Return(nil)
+		tx.On("GetActiveHold", ctx, int64(1)).Return((*Hold)(nil), nil)
+		tx.On("GetRateHistory", ctx, int64(1)).Return([]RateChange{}, nil)
+		tx.On("GetScheduleAdjustments", ctx, int64(1)).Return([]ScheduleAdjustment{}, nil)
+		tx.On("GetPrepayments", ctx, int64(1)).Return([]Prepayment{}, nil)
+		tx.On("GetScheduleForUpdate", ctx, int64(1)).Return(stored, nil)
+		tx.On("ApplyScheduleChanges", ctx, int64(1), mock.MatchedBy(func(changes []ScheduleChange) bool {
+			return len(changes) == 2 && changes[0].WeekNumber == 2 && changes[1].WeekNumber == 3
+		})).Return(nil)
+		tx.On("RepriceLoan", ctx, mock.MatchedBy(func(r *Repricing) bool {
+			return r.Change.Rate == 0.16 && r.Change.PreviousRate == 0.1 && *r.Change.ChangedBy == "ops" &&
+				r.Change.CreatedAt.Equal(now) && r.TotalLoanAmount == 342
+		})).Return(nil)
+
+		repricing, err := service.RepriceLoan(ctx, 1, 0.16, effectiveFrom, "ops")
+
+		require.NoError(t, err)
+		assert.Equal(t, 116.0, repricing.WeeklyPaymentAmount)
+		mockRepo.AssertExpectations(t)
+		tx.AssertExpectations(t)
+	})
+
+	t.Run("takes the current rate from the history read under the lock", func(t *testing.T) {
+		l, stored := newLoan(t)
+		mockRepo := new(MockRepository)
+		tx := new(MockTxRepository)
+		service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.NewFake(now), logger)
+		mockRepo.On("GetLoanByID", ctx, int64(1)).Return(l, nil)
+		mockRepo.On("WithinTransaction", ctx).Return(tx, nil)
+		tx.On("LockLoanForPayment", ctx, int64(1)).Return(nil)
+		tx.On("GetActiveHold", ctx, int64(1)).Return((*Hold)(nil), nil)
+		tx.On("GetRateHistory", ctx, int64(1)).Return([]RateChange{{LoanID: 1, PreviousRate: 0.1, Rate: 0.16, EffectiveFrom: effectiveFrom}}, nil)
+		tx.On("GetScheduleAdjustments", ctx, int64(1)).Return([]ScheduleAdjustment{}, nil)
+		tx.On("GetPrepayments", ctx, int64(1)).Return([]Prepayment{}, nil)
+		tx.On("GetScheduleForUpdate", ctx, int64(1)).Return(stored, nil)
+
+		_, err := service.RepriceLoan(ctx, 1, 0.16, effectiveFrom, "ops")
+
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+		tx.AssertNotCalled(t, "RepriceLoan", mock.Anything, mock.Anything)
+	})
+
+	t.Run("refuses a loan on hold", func(t *testing.T) {
+		l, _ := newLoan(t)
+		mockRepo := new(MockRepository)
+		tx := new(MockTxRepository)
+		service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.NewFake(now), logger)
+		mockRepo.On("GetLoanByID", ctx, int64(1)).Return(l, nil)
+		mockRepo.On("WithinTransaction", ctx).Return(tx, nil)
+		tx.On("LockLoanForPayment", ctx, int64(1)).Return(nil)
+		tx.On("GetActiveHold", ctx, int64(1)).Return(&Hold{ID: 3, LoanID: 1, Reason: "disputed"}, nil)
+
+		_, err := service.RepriceLoan(ctx, 1, 0.16, effectiveFrom, "ops")
+
+		assert.ErrorIs(t, err, apperrors.ErrLoanOnHold)
+		tx.AssertNotCalled(t, "GetScheduleForUpdate", mock.Anything, mock.Anything)
+		tx.AssertNotCalled(t, "ApplyScheduleChanges", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("reports a failed write as internal", func(t *testing.T) {
+		l, stored := newLoan(t)
+		mockRepo := new(MockRepository)
+		tx := new(MockTxRepository)
+		service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.NewFake(now), logger)
+		mockRepo.On("GetLoanByID", ctx, int64(1)).Return(l, nil)
+		mockRepo.On("WithinTransaction", ctx).Return(tx, nil)
+		tx.On("LockLoanForPayment", ctx, int64(1)).Return(nil)
+		tx.On("GetActiveHold", ctx, int64(1)).Return((*Hold)(nil), nil)
+		tx.On("GetRateHistory", ctx, int64(1)).Return([]RateChange{}, nil)
+		tx.On("GetScheduleAdjustments", ctx, int64(1)).Return([]ScheduleAdjustment{}, nil)
+		tx.On("GetPrepayments", ctx, int64(1)).Return([]Prepayment{}, nil)
+		tx.On("GetScheduleForUpdate", ctx, int64(1)).Return(stored, nil)
+		tx.On("ApplyScheduleChanges", ctx, int64(1), mock.Anything).Return(nil)
+		tx.On("RepriceLoan", ctx, mock.Anything).Return(apperrors.ErrDatabase)
+
+		_, err := service.RepriceLoan(ctx, 1, 0.16, effectiveFrom, "ops")
+
+		assert.ErrorIs(t, err, apperrors.ErrInternalServer)
+	})
+
+	t.Run("validates before reading the loan", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.NewFake(now), logger)
+
+		_, err := service.RepriceLoan(ctx, 1, -0.1, effectiveFrom, "ops")
+
+		assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+		mockRepo.AssertNotCalled(t, "GetLoanByID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("reports an unknown loan", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.NewFake(now), logger)
+		mockRepo.On("GetLoanByID", ctx, int64(1)).Return((*Loan)(nil), apperrors.ErrNotFound)
+
+		_, err := service.RepriceLoan(ctx, 1, 0.16, effectiveFrom, "ops")
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+
+	t.Run("forbids customer scope", func(t *testing.T) {
+		service := NewLoanService(new(MockRepository), new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.NewFake(now), logger)
+
+		_, err := service.RepriceLoan(scope.WithCustomer(ctx, 5), 1, 0.16, effectiveFrom, "ops")
+
+		assert.ErrorIs(t, err, apperrors.ErrForbidden)
+	})
+}
+
+func TestRepriceLoans(t *testing.T) {
+	ctx := context.Background()
+	effectiveFrom := time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC)
+	newLoan := func(t *testing.T, id int64, rate float64) (*Loan, []ScheduleEntry) {
+		l, err := NewLoan(300, 3, rate, time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC))
+		require.NoError(t, err)
+		l.ID = id
+		schedule, err := l.GenerateSchedule()
+		require.NoError(t, err)
+		return l, schedule
+	}
+
+	t.Run("reprices the loans on the base rate and skips the rest", func(t *testing.T) {
+		onBase, onBaseSchedule := newLoan(t, 1, 0.1)
+		paidAhead, paidAheadSchedule := newLoan(t, 2, 0.1)
+		paidAheadSchedule[1].Status = PaymentStatusPaid
+		paidAheadSchedule[1].PaidAmount = 110
+		other, _ := newLoan(t, 3, 0.12)
+		paidOff, _ := newLoan(t, 4, 0.1)
+		paidOff.Status = StatusPaidOff
+
+		mockRepo := new(MockRepository)
+		tx := new(MockTxRepository)
+		service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
+		mockRepo.On("StreamLoans", ctx, LoanFilter{}).Return([]*Loan{onBase, paidAhead, other, paidOff}, nil)
+		mockRepo.On("WithinTransaction", ctx).Return(tx, nil)
+		for _, id := range []int64{1, 2} {
+			tx.On("LockLoanForPayment", ctx, id).Return(nil)
+			tx.On("GetActiveHold", ctx, id).Return((*Hold)(nil), nil)
+			tx.On("GetRateHistory", ctx, id).Return([]RateChange{}, nil)
+			tx.On("GetScheduleAdjustments", ctx, id).Return([]ScheduleAdjustment{}, nil)
+			tx.On("GetPrepayments", ctx, id).Return([]Prepayment{}, nil)
+		}
+		tx.On("GetScheduleForUpdate", ctx, int64(1)).Return(onBaseSchedule, nil)
+		tx.On("GetScheduleForUpdate", ctx, int64(2)).Return(paidAheadSchedule, nil)
+		tx.On("ApplyScheduleChanges", ctx, int64(1), mock.Anything).Return(nil)
+		tx.On("RepriceLoan", ctx, mock.MatchedBy(func(r *Repricing) bool { return r.Change.LoanID == 1 })).Return(nil)
+
+		baseRate := 0.1
+		report, err := service.RepriceLoans(ctx, RepricingFilter{CurrentRate: &baseRate}, 0.16, effectiveFrom, "treasury")
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, report.Repriced)
+		require.Len(t, report.Skipped, 1)
+		assert.Equal(t, int64(2), report.Skipped[0].LoanID)
+		assert.Contains(t, report.Skipped[0].Reason, "already paid")
+		tx.AssertExpectations(t)
+		tx.AssertNotCalled(t, "LockLoanForPayment", ctx, int64(3))
+		tx.AssertNotCalled(t, "LockLoanForPayment", ctx, int64(4))
+	})
+
+	t.Run("skips loans on hold", func(t *testing.T) {
+		held, _ := newLoan(t, 1, 0.1)
+		mockRepo := new(MockRepository)
+		tx := new(MockTxRepository)
+		service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
+		mockRepo.On("StreamLoans", ctx, LoanFilter{}).Return([]*Loan{held}, nil)
+		mockRepo.On("WithinTransaction", ctx).Return(tx, nil)
+		tx.On("LockLoanForPayment", ctx, int64(1)).Return(nil)
+		tx.On("GetActiveHold", ctx, int64(1)).Return(&Hold{ID: 3, LoanID: 1, Reason: "disputed"}, nil)
+
+		report, err := service.RepriceLoans(ctx, RepricingFilter{}, 0.16, effectiveFrom, "treasury")
+
+		require.NoError(t, err)
+		assert.Zero(t, report.Repriced)
+		require.Len(t, report.Skipped, 1)
+		assert.Contains(t, report.Skipped[0].Reason, "on hold")
+		tx.AssertNotCalled(t, "RepriceLoan", mock.Anything, mock.Anything)
+	})
+
+	t.Run("stops on a database failure", func(t *testing.T) {
+		l, schedule := newLoan(t, 1, 0.1)
+		mockRepo := new(MockRepository)
+		tx := new(MockTxRepository)
+		service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
+		mockRepo.On("StreamLoans", ctx, LoanFilter{}).Return([]*Loan{l}, nil)
+		mockRepo.On("WithinTransaction", ctx).Return(tx, nil)
+		tx.On("LockLoanForPayment", ctx, int64(1)).Return(nil)
+		tx.On("GetActiveHold", ctx, int64(1)).Return((*Hold)(nil), nil)
+		tx.On("GetRateHistory", ctx, int64(1)).Return([]RateChange{}, nil)
+		tx.On("GetScheduleAdjustments", ctx, int64(1)).Return([]ScheduleAdjustment{}, nil)
+		tx.On("GetPrepayments", ctx, int64(1)).Return([]Prepayment{}, nil)
+		tx.On("GetScheduleForUpdate", ctx, int64(1)).Return(schedule, nil)
+		tx.On("ApplyScheduleChanges", ctx, int64(1), mock.Anything).Return(apperrors.ErrDatabase)
+
+		_, err := service.RepriceLoans(ctx, RepricingFilter{}, 0.16, effectiveFrom, "treasury")
+
+		assert.ErrorIs(t, err, apperrors.ErrInternalServer)
+	})
+
+	t.Run("validates before listing loans", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
+
+		_, err := service.RepriceLoans(ctx, RepricingFilter{}, 0.16, time.Time{}, "treasury")
+
+		assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+		mockRepo.AssertNotCalled(t, "StreamLoans", mock.Anything, mock.Anything)
+	})
+
+	t.Run("forbids customer scope", func(t *testing.T) {
+		service := NewLoanService(new(MockRepository), new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
+
+		_, err := service.RepriceLoans(scope.WithCustomer(ctx, 5), RepricingFilter{}, 0.16, effectiveFrom, "treasury")
+
+		assert.ErrorIs(t, err, apperrors.ErrForbidden)
+	})
+}
+
+func TestGetLoanIncludesHoldForStaff(t *testing.T) {
+	mockRepo := new(MockRepository)
+	service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
+
+	ctx := context.Background()
+	hold := &Hold{ID: 9, LoanID: 1, Reason: "disputed"}
+	mockRepo.On("GetLoanByID", ctx, int64(1)).Return(&Loan{ID: 1}, nil)
+	mockRepo.On("GetActiveHold", ctx, int64(1)).Return(hold, nil)
+	mockRepo.On("GetRateHistory", ctx, int64(1)).Return([]RateChange{}, nil)
+	mockRepo.On("GetScheduleAdjustments", ctx, int64(1)).Return([]ScheduleAdjustment{}, nil)
+	mockRepo.On("GetPrepayments", ctx, int64(1)).Return([]Prepayment{}, nil)
+
+	result, err := service.GetLoan(ctx, 1, false)
+
+	require.NoError(t, err)
+	assert.Equal(t, hold, result.Hold)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestMakePaymentTransactionFailure(t *testing.T) {
+	mockRepo := new(MockRepository)
+	service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
+
+	ctx := context.Background()
+	mockRepo.On("WithinTransaction", ctx).Return(nil, apperrors.ErrDatabase)
+
+	err := service.MakePayment(ctx, 1, 100, PaymentDetails{})
+
+	assert.ErrorIs(t, err, apperrors.ErrInternalServer)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestMakePaymentRejectsInvalidDetails(t *testing.T) {
+	mockRepo := new(MockRepository)
+	service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
+
+	for name, details := range map[string]PaymentDetails{
+		"unknown channel":   {Channel: "CHEQUE"},
+		"long reference":    {Channel: ChannelGateway, Reference: strings.Repeat("x", 101)},
+		"long collector ID": {Channel: ChannelCash, CollectorID: strings.Repeat("x", 65)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := service.MakePayment(context.Background(), 1, 100, details)
+
+			assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+		})
+	}
+	mockRepo.AssertNotCalled(t, "WithinTransaction", mock.Anything)
+}
+
+func TestCollectionsByChannel(t *testing.T) {
+	mockRepo := new(MockRepository)
+	service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
+	ctx := context.Background()
+	from := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)
+
+	t.Run("lists every channel and the totals", func(t *testing.T) {
+		mockRepo.On("SumPaymentsByChannel", ctx, from, to.AddDate(0, 0, 1)).Return([]ChannelCollections{
+			{Channel: ChannelBankTransfer, Payments: 2, Amount: 220.004},
+			{Channel: ChannelCash, Payments: 1, Amount: 110},
+		}, nil).Once()
+
+		report, err := service.CollectionsByChannel(ctx, from, to.Add(15*time.Hour))
+
+		require.NoError(t, err)
+		assert.Equal(t, from, report.From)
+		assert.Equal(t, to, report.To)
+		require.Len(t, report.Channels, len(PaymentChannels))
+		assert.Equal(t, ChannelCollections{Channel: ChannelCash, Payments: 1, Amount: 110}, report.Channels[0])
+		assert.Equal(t, ChannelCollections{Channel: ChannelBankTransfer, Payments: 2, Amount: 220}, report.Channels[1])
+		assert.Equal(t, ChannelCollections{Channel: ChannelGateway}, report.Channels[2])
+		assert.Equal(t, 3, report.TotalPayments)
+		assert.Equal(t, Money(330), report.TotalAmount)
+	})
+
+	t.Run("rejects an inverted range", func(t *testing.T) {
+		_, err := service.CollectionsByChannel(ctx, to, from)
+
+		assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+	})
+
+	t.Run("forbids customer scope", func(t *testing.T) {
+		_, err := service.CollectionsByChannel(scope.WithCustomer(ctx, 5), from, to)
+
+		assert.ErrorIs(t, err, apperrors.ErrForbidden)
+	})
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestMakePaymentRejectsAmountThatDoesNotSettleInstallment(t *testing.T) {
+	mockRepo := new(MockRepository)
+	service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
+
+	ctx := context.Background()
+	tx := new(MockTxRepository)
+	entry := &ScheduleEntry{DueAmount: 110, PaidAmount: 10, Status: PaymentStatusPending}
+
+	mockRepo.On("WithinTransaction", ctx).Return(tx, nil)
+	tx.On("LockLoanForPayment", ctx, int64(1)).Return(nil)
+	tx.On("GetActiveHold", ctx, int64(1)).Return((*Hold)(nil), nil)
+	tx.On("FindOldestUnpaidEntryForUpdate", ctx, int64(1)).Return(entry, nil)
+
+	err := service.MakePayment(ctx, 1, 110, PaymentDetails{})
+
+	var amountErr *apperrors.PaymentAmountError
+	require.True(t, errors.As(err, &amountErr))
+	assert.Equal(t, Money(100), amountErr.Expected, "earlier partial payments are deducted")
+	assert.Equal(t, PaymentStatusPending, entry.Status)
+	tx.AssertNotCalled(t, "UpdateScheduleEntry", mock.Anything, mock.Anything)
 	mockRepo.AssertExpectations(t)
+	tx.AssertExpectations(t)
 }
 
 func TestGetLoan(t *testing.T) {
 	mockRepo := new(MockRepository)
 
 	mockCustomerService := new(MockCustomerService)
-	service := NewLoanService(mockRepo, mockCustomerService, logger)
+	service := NewLoanService(mockRepo, mockCustomerService, DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
 
 	ctx := context.Background()
 	loanID := int64(1)
 	expectedLoan := &Loan{}
 
 	mockRepo.On("GetLoanByID", ctx, loanID).Return(expectedLoan, nil)
-	mockRepo.On("GetScheduleByLoanID", ctx, loanID).Return([]ScheduleEntry{}, nil)
+	mockRepo.On("GetActiveHold", ctx, loanID).Return((*Hold)(nil), nil)
+	mockRepo.On("GetRateHistory", ctx, loanID).Return([]RateChange{}, nil)
+	mockRepo.On("GetScheduleAdjustments", ctx, loanID).Return([]ScheduleAdjustment{}, nil)
+	mockRepo.On("GetPrepayments", ctx, loanID).Return([]Prepayment{}, nil)
 
-	result, err := service.GetLoan(ctx, loanID)
+	result, err := service.GetLoan(ctx, loanID, false)
 
 	assert.NoError(t, err)
 	assert.Equal(t, expectedLoan, result)
 	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "GetLoanWithSchedule", mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "GetScheduleByLoanID", mock.Anything, mock.Anything)
+}
+
+func TestGetLoanWithSchedule(t *testing.T) {
+	mockRepo := new(MockRepository)
+	service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
+
+	ctx := context.Background()
+	schedule := []ScheduleEntry{{ID: 10, LoanID: 1, WeekNumber: 1, DueAmount: 100, Status: PaymentStatusPending}}
+	mockRepo.On("GetLoanWithSchedule", ctx, int64(1)).Return(&Loan{ID: 1, Schedule: schedule}, nil)
+	mockRepo.On("GetActiveHold", ctx, int64(1)).Return((*Hold)(nil), nil)
+	mockRepo.On("GetRateHistory", ctx, int64(1)).Return([]RateChange{}, nil)
+	mockRepo.On("GetScheduleAdjustments", ctx, int64(1)).Return([]ScheduleAdjustment{}, nil)
+	mockRepo.On("GetPrepayments", ctx, int64(1)).Return([]Prepayment{}, nil)
+
+	result, err := service.GetLoan(ctx, 1, true)
+
+	require.NoError(t, err)
+	assert.Equal(t, schedule, result.Schedule)
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "GetLoanByID", mock.Anything, mock.Anything)
+}
+
+func TestGetLoanWithScheduleFails(t *testing.T) {
+	mockRepo := new(MockRepository)
+	service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
+
+	ctx := context.Background()
+	mockRepo.On("GetLoanWithSchedule", ctx, int64(1)).Return((*Loan)(nil), errors.Join(apperrors.ErrDatabase, errors.New("connection reset"))).Once()
+	mockRepo.On("GetLoanWithSchedule", ctx, int64(2)).Return((*Loan)(nil), apperrors.ErrNotFound).Once()
+
+	result, err := service.GetLoan(ctx, 1, true)
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, apperrors.ErrInternalServer, "a failed schedule read is not swallowed")
+
+	result, err = service.GetLoan(ctx, 2, true)
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetLoanByExternalRef(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockCustomerService := new(MockCustomerService)
+	service := NewLoanService(mockRepo, mockCustomerService, DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
+
+	ctx := context.Background()
+	externalRef := "LOS-42"
+	expectedLoan := &Loan{ID: 42, ExternalRef: &externalRef}
+
+	mockRepo.On("GetLoanByExternalRef", ctx, externalRef).Return(expectedLoan, nil)
+	mockRepo.On("GetLoanByID", ctx, int64(42)).Return(expectedLoan, nil)
+	mockRepo.On("GetActiveHold", ctx, int64(42)).Return((*Hold)(nil), nil)
+	mockRepo.On("GetRateHistory", ctx, int64(42)).Return([]RateChange{}, nil)
+	mockRepo.On("GetScheduleAdjustments", ctx, int64(42)).Return([]ScheduleAdjustment{}, nil)
+	mockRepo.On("GetPrepayments", ctx, int64(42)).Return([]Prepayment{}, nil)
+
+	result, err := service.GetLoanByExternalRef(ctx, externalRef, false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, expectedLoan, result)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetLoanByExternalRefNotFound(t *testing.T) {
+	mockRepo := new(MockRepository)
+	service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
+
+	ctx := context.Background()
+	mockRepo.On("GetLoanByExternalRef", ctx, "missing").Return((*Loan)(nil), apperrors.ErrNotFound)
+
+	result, err := service.GetLoanByExternalRef(ctx, "missing", false)
+
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+}
+
+func TestCreateLoanDuplicateExternalRef(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockCustomerService := new(MockCustomerService)
+	service := NewLoanService(mockRepo, mockCustomerService, DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
+
+	ctx := context.Background()
+	customerID := int64(1)
+	mockCustomerService.On("GetCustomer", ctx, customerID).Return(&customer.Customer{CustomerID: customerID, Active: true}, nil)
+	mockCustomerService.On("RequestRiskScore", ctx, mock.Anything, customer.RiskScoreLoanApplication, mock.Anything).Return()
+	mockRepo.On("CreateLoan", ctx, customerID, mock.MatchedBy(func(l *Loan) bool {
+		return l.ExternalRef != nil && *l.ExternalRef == "LOS-42"
+	}), mock.Anything).Return((*Loan)(nil), apperrors.ErrAlreadyExists)
+
+	result, err := service.CreateLoan(ctx, customerID, Money(1000), 52, Money(5), time.Now(), "LOS-42", uuid.Nil, "")
+
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, apperrors.ErrAlreadyExists)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCreateLoanPublicIDReplay(t *testing.T) {
+	ctx := context.Background()
+	customerID := int64(1)
+	publicID := uuid.New()
+
+	t.Run("returns the customer's existing loan", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockCustomerService := new(MockCustomerService)
+		service := NewLoanService(mockRepo, mockCustomerService, DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
+		loanID := int64(42)
+		existing := &Loan{ID: loanID, PublicID: publicID}
+
+		mockCustomerService.On("GetCustomer", ctx, customerID).Return(&customer.Customer{CustomerID: customerID, Active: true, LoanID: &loanID}, nil)
+		mockRepo.On("GetLoanByPublicID", ctx, publicID).Return(existing, nil)
+		mockRepo.On("GetLoanByID", ctx, loanID).Return(existing, nil)
+		mockRepo.On("GetActiveHold", ctx, loanID).Return((*Hold)(nil), nil)
+		mockRepo.On("GetRateHistory", ctx, loanID).Return([]RateChange{}, nil)
+		mockRepo.On("GetScheduleAdjustments", ctx, loanID).Return([]ScheduleAdjustment{}, nil)
+		mockRepo.On("GetPrepayments", ctx, loanID).Return([]Prepayment{}, nil)
+
+		result, err := service.CreateLoan(ctx, customerID, Money(1000), 52, Money(5), time.Now(), "", publicID, "")
+
+		assert.NoError(t, err)
+		assert.Equal(t, existing, result)
+		mockRepo.AssertNotCalled(t, "CreateLoan", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockCustomerService.AssertNotCalled(t, "RequestRiskScore", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("rejects a public ID held by another customer's loan", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockCustomerService := new(MockCustomerService)
+		service := NewLoanService(mockRepo, mockCustomerService, DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
+
+		mockCustomerService.On("GetCustomer", ctx, customerID).Return(&customer.Customer{CustomerID: customerID, Active: true}, nil)
+		mockRepo.On("GetLoanByPublicID", ctx, publicID).Return(&Loan{ID: 7, PublicID: publicID}, nil)
+
+		result, err := service.CreateLoan(ctx, customerID, Money(1000), 52, Money(5), time.Now(), "", publicID, "")
+
+		assert.Nil(t, result)
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+		mockRepo.AssertNotCalled(t, "CreateLoan", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestResolveLoanID(t *testing.T) {
+	mockRepo := new(MockRepository)
+	service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
+
+	ctx := context.Background()
+	known, unknown := uuid.New(), uuid.New()
+	mockRepo.On("GetLoanByPublicID", ctx, known).Return(&Loan{ID: 12, PublicID: known}, nil)
+	mockRepo.On("GetLoanByPublicID", ctx, unknown).Return((*Loan)(nil), apperrors.ErrNotFound)
+
+	id, err := service.ResolveLoanID(ctx, known)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(12), id)
+
+	_, err = service.ResolveLoanID(ctx, unknown)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
 }
 
 func TestGetLoanSchedule(t *testing.T) {
 	mockRepo := new(MockRepository)
 
 	mockCustomerService := new(MockCustomerService)
-	service := NewLoanService(mockRepo, mockCustomerService, logger)
+	service := NewLoanService(mockRepo, mockCustomerService, DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
 
 	ctx := context.Background()
 	loanID := int64(1)
@@ -299,3 +1545,909 @@ func TestGetLoanSchedule(t *testing.T) {
 	assert.Equal(t, expectedSchedule, result)
 	mockRepo.AssertExpectations(t)
 }
+
+func TestListPayments(t *testing.T) {
+	ctx := context.Background()
+	collector := "alice"
+	payments := func() []Payment {
+		return []Payment{{ID: 3, LoanID: 1, Amount: 110, Channel: ChannelCash, CollectorID: &collector}}
+	}
+
+	t.Run("returns the latest payments", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
+		mockRepo.On("GetLoanByID", ctx, int64(1)).Return(&Loan{ID: 1}, nil)
+		mockRepo.On("ListPayments", ctx, int64(1), 5).Return(payments(), nil)
+
+		result, err := service.ListPayments(ctx, 1, 5)
+
+		require.NoError(t, err)
+		assert.Equal(t, payments(), result)
+	})
+
+	t.Run("hides collectors from the customer", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockCustomerService := new(MockCustomerService)
+		service := NewLoanService(mockRepo, mockCustomerService, DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
+		ownLoanID := int64(1)
+		scoped := scope.WithCustomer(ctx, 42)
+		mockCustomerService.On("GetCustomer", scoped, int64(42)).Return(&customer.Customer{CustomerID: 42, LoanID: &ownLoanID}, nil)
+		mockRepo.On("GetLoanByID", scoped, int64(1)).Return(&Loan{ID: 1}, nil)
+		mockRepo.On("ListPayments", scoped, int64(1), 5).Return(payments(), nil)
+
+		result, err := service.ListPayments(scoped, 1, 5)
+
+		require.NoError(t, err)
+		require.Len(t, result, 1)
+		assert.Nil(t, result[0].CollectorID)
+	})
+
+	t.Run("unknown loan", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
+		mockRepo.On("GetLoanByID", ctx, int64(9)).Return((*Loan)(nil), apperrors.ErrNotFound)
+
+		_, err := service.ListPayments(ctx, 9, 5)
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+		mockRepo.AssertNotCalled(t, "ListPayments", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("rejects a limit below one", func(t *testing.T) {
+		_, err := NewLoanService(new(MockRepository), new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger).ListPayments(ctx, 1, 0)
+
+		assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+	})
+}
+
+func TestGetOutstandingCustomerScope(t *testing.T) {
+	ownLoanID := int64(1)
+	customerID := int64(42)
+
+	t.Run("allows access to the scoped customer's own loan", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockCustomerService := new(MockCustomerService)
+		service := NewLoanService(mockRepo, mockCustomerService, DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
+		ctx := scope.WithCustomer(context.Background(), customerID)
+
+		mockCustomerService.On("GetCustomer", ctx, customerID).Return(&customer.Customer{CustomerID: customerID, LoanID: &ownLoanID}, nil)
+		expectOutstandingLookups(mockRepo, ctx, ownLoanID, nil)
+
+		result, err := service.GetOutstanding(ctx, ownLoanID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, Money(220), result)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("forbids access to another customer's loan", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockCustomerService := new(MockCustomerService)
+		service := NewLoanService(mockRepo, mockCustomerService, DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
+		ctx := scope.WithCustomer(context.Background(), customerID)
+
+		mockCustomerService.On("GetCustomer", ctx, customerID).Return(&customer.Customer{CustomerID: customerID, LoanID: &ownLoanID}, nil)
+
+		_, err := service.GetOutstanding(ctx, int64(99))
+
+		assert.ErrorIs(t, err, apperrors.ErrForbidden)
+		mockRepo.AssertNotCalled(t, "GetLoanByID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("forbids payments with customer scope", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
+		ctx := scope.WithCustomer(context.Background(), customerID)
+
+		err := service.MakePayment(ctx, ownLoanID, Money(100), PaymentDetails{})
+
+		assert.ErrorIs(t, err, apperrors.ErrForbidden)
+		mockRepo.AssertNotCalled(t, "WithinTransaction", mock.Anything)
+	})
+}
+
+func TestApplyScheduleAdjustment(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 15, 9, 0, 0, 0, time.UTC)
+	startsOn := time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC)
+	newLoan := func(t *testing.T) (*Loan, []ScheduleEntry) {
+		l, err := NewLoan(300, 3, 0.1, time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC))
+		require.NoError(t, err)
+		l.ID = 1
+		schedule, err := l.GenerateSchedule()
+		require.NoError(t, err)
+		return l, schedule
+	}
+	holiday := ScheduleAdjustment{Kind: AdjustmentPaymentHoliday, StartsOn: startsOn, Weeks: 1, Reason: " medical leave "}
+
+	t.Run("moves the schedule and stores the adjustment", func(t *testing.T) {
+		l, stored := newLoan(t)
+		mockRepo := new(MockRepository)
+		tx := new(MockTxRepository)
+		service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.NewFake(now), logger)
+		mockRepo.On("GetLoanByID", ctx, int64(1)).Return(l, nil)
+		mockRepo.On("WithinTransaction", ctx).Return(tx, nil)
+		tx.On("LockLoanForPayment", ctx, int64(1)).Return(nil)
+		tx.On("GetActiveHold", ctx, int64(1)).Return((*Hold)(nil), nil)
+		tx.On("GetRateHistory", ctx, int64(1)).Return([]RateChange{}, nil)
+		tx.On("GetScheduleAdjustments", ctx, int64(1)).Return([]ScheduleAdjustment{}, nil)
+		tx.On("GetPrepayments", ctx, int64(1)).Return([]Prepayment{}, nil)
+		tx.On("GetScheduleForUpdate", ctx, int64(1)).Return(stored, nil)
+		tx.On("ApplyScheduleChanges", ctx, int64(1), mock.MatchedBy(func(changes []ScheduleChange) bool {
+			return len(changes) == 2 && changes[0].After.DueDate.Equal(time.Date(2025, 1, 27, 0, 0, 0, 0, time.UTC))
+		})).Return(nil)
+		tx.On("AddScheduleAdjustment", ctx, mock.MatchedBy(func(p *AdjustmentPlan) bool {
+			a := p.Adjustment
+			return a.LoanID == 1 && a.Reason == "medical leave" && *a.AppliedBy == "ops" && a.AppliedAt.Equal(now) && p.TotalLoanAmount == 330
+		})).Return(nil)
+
+		plan, err := service.ApplyScheduleAdjustment(ctx, 1, holiday, "ops")
+
+		require.NoError(t, err)
+		assert.Len(t, plan.Changes, 2)
+		mockRepo.AssertExpectations(t)
+		tx.AssertExpectations(t)
+	})
+
+	t.Run("refuses a loan on hold", func(t *testing.T) {
+		l, _ := newLoan(t)
+		mockRepo := new(MockRepository)
+		tx := new(MockTxRepository)
+		service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.NewFake(now), logger)
+		mockRepo.On("GetLoanByID", ctx, int64(1)).Return(l, nil)
+		mockRepo.On("WithinTransaction", ctx).Return(tx, nil)
+		tx.On("LockLoanForPayment", ctx, int64(1)).Return(nil)
+		tx.On("GetActiveHold", ctx, int64(1)).Return(&Hold{ID: 3, LoanID: 1, Reason: "disputed"}, nil)
+
+		_, err := service.ApplyScheduleAdjustment(ctx, 1, holiday, "ops")
+
+		assert.ErrorIs(t, err, apperrors.ErrLoanOnHold)
+		tx.AssertNotCalled(t, "GetScheduleForUpdate", mock.Anything, mock.Anything)
+		tx.AssertNotCalled(t, "ApplyScheduleChanges", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("plans against the adjustments read under the lock", func(t *testing.T) {
+		l, stored := newLoan(t)
+		mockRepo := new(MockRepository)
+		tx := new(MockTxRepository)
+		service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.NewFake(now), logger)
+		mockRepo.On("GetLoanByID", ctx, int64(1)).Return(l, nil)
+		mockRepo.On("WithinTransaction", ctx).Return(tx, nil)
+		tx.On("LockLoanForPayment", ctx, int64(1)).Return(nil)
+		tx.On("GetActiveHold", ctx, int64(1)).Return((*Hold)(nil), nil)
+		tx.On("GetRateHistory", ctx, int64(1)).Return([]RateChange{}, nil)
+		tx.On("GetScheduleAdjustments", ctx, int64(1)).Return([]ScheduleAdjustment{
+			{ID: 2, LoanID: 1, Kind: AdjustmentPaymentHoliday, StartsOn: startsOn, Weeks: 2, Reason: "leave"},
+		}, nil)
+		tx.On("GetPrepayments", ctx, int64(1)).Return([]Prepayment{}, nil)
+		tx.On("GetScheduleForUpdate", ctx, int64(1)).Return(stored, nil)
+
+		_, err := service.ApplyScheduleAdjustment(ctx, 1, holiday, "ops")
+
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+		tx.AssertNotCalled(t, "AddScheduleAdjustment", mock.Anything, mock.Anything)
+	})
+
+	t.Run("reports a failed write as internal", func(t *testing.T) {
+		l, stored := newLoan(t)
+		mockRepo := new(MockRepository)
+		tx := new(MockTxRepository)
+		service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.NewFake(now), logger)
+		mockRepo.On("GetLoanByID", ctx, int64(1)).Return(l, nil)
+		mockRepo.On("WithinTransaction", ctx).Return(tx, nil)
+		tx.On("LockLoanForPayment", ctx, int64(1)).Return(nil)
+		tx.On("GetActiveHold", ctx, int64(1)).Return((*Hold)(nil), nil)
+		tx.On("GetRateHistory", ctx, int64(1)).Return([]RateChange{}, nil)
+		tx.On("GetScheduleAdjustments", ctx, int64(1)).Return([]ScheduleAdjustment{}, nil)
+		tx.On("GetPrepayments", ctx, int64(1)).Return([]Prepayment{}, nil)
+		tx.On("GetScheduleForUpdate", ctx, int64(1)).Return(stored, nil)
+		tx.On("ApplyScheduleChanges", ctx, int64(1), mock.Anything).Return(nil)
+		tx.On("AddScheduleAdjustment", ctx, mock.Anything).Return(apperrors.ErrDatabase)
+
+		_, err := service.ApplyScheduleAdjustment(ctx, 1, holiday, "ops")
+
+		assert.ErrorIs(t, err, apperrors.ErrInternalServer)
+	})
+
+	t.Run("validates before reading the loan", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.NewFake(now), logger)
+
+		_, err := service.ApplyScheduleAdjustment(ctx, 1, ScheduleAdjustment{Kind: AdjustmentZeroInterest, StartsOn: startsOn, Weeks: 1}, "ops")
+
+		assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+		mockRepo.AssertNotCalled(t, "GetLoanByID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("forbids customer scope", func(t *testing.T) {
+		service := NewLoanService(new(MockRepository), new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.NewFake(now), logger)
+
+		_, err := service.ApplyScheduleAdjustment(scope.WithCustomer(ctx, 5), 1, holiday, "ops")
+
+		assert.ErrorIs(t, err, apperrors.ErrForbidden)
+	})
+}
+
+func TestRemoveScheduleAdjustment(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 15, 9, 0, 0, 0, time.UTC)
+	adjustments := []ScheduleAdjustment{{ID: 2, LoanID: 1, Kind: AdjustmentZeroInterest, StartsOn: time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC), Weeks: 1, Reason: "promotion"}}
+	newLoan := func(t *testing.T) (*Loan, []ScheduleEntry) {
+		l, err := NewLoan(300, 3, 0.1, time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC))
+		require.NoError(t, err)
+		l.ID = 1
+		l.Adjustments = adjustments
+		l.TotalLoanAmount = 320
+		schedule, err := l.GenerateSchedule()
+		require.NoError(t, err)
+		l.Adjustments = nil
+		return l, schedule
+	}
+
+	t.Run("restores the schedule and marks the adjustment removed", func(t *testing.T) {
+		l, stored := newLoan(t)
+		require.Equal(t, 100.0, stored[1].DueAmount)
+		mockRepo := new(MockRepository)
+		tx := new(MockTxRepository)
+		service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.NewFake(now), logger)
+		mockRepo.On("GetLoanByID", ctx, int64(1)).Return(l, nil)
+		mockRepo.On("WithinTransaction", ctx).Return(tx, nil)
+		tx.On("LockLoanForPayment", ctx, int64(1)).Return(nil)
+		tx.On("GetActiveHold", ctx, int64(1)).Return((*Hold)(nil), nil)
+		tx.On("GetRateHistory", ctx, int64(1)).Return([]RateChange{}, nil)
+		tx.On("GetScheduleAdjustments", ctx, int64(1)).Return(adjustments, nil)
+		tx.On("GetPrepayments", ctx, int64(1)).Return([]Prepayment{}, nil)
+		tx.On("GetScheduleForUpdate", ctx, int64(1)).Return(stored, nil)
+		tx.On("ApplyScheduleChanges", ctx, int64(1), mock.MatchedBy(func(changes []ScheduleChange) bool {
+			return len(changes) == 1 && changes[0].After.DueAmount == 110
+		})).Return(nil)
+		tx.On("RemoveScheduleAdjustment", ctx, mock.MatchedBy(func(p *AdjustmentPlan) bool {
+			a := p.Adjustment
+			return a.ID == 2 && *a.RemovedBy == "ops" && a.RemovedAt.Equal(now) && p.TotalLoanAmount == 330
+		})).Return(nil)
+
+		_, err := service.RemoveScheduleAdjustment(ctx, 1, 2, "ops")
+
+		require.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+		tx.AssertExpectations(t)
+	})
+
+	t.Run("refuses a loan on hold", func(t *testing.T) {
+		l, _ := newLoan(t)
+		mockRepo := new(MockRepository)
+		tx := new(MockTxRepository)
+		service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.NewFake(now), logger)
+		mockRepo.On("GetLoanByID", ctx, int64(1)).Return(l, nil)
+		mockRepo.On("WithinTransaction", ctx).Return(tx, nil)
+		tx.On("LockLoanForPayment", ctx, int64(1)).Return(nil)
+		tx.On("GetActiveHold", ctx, int64(1)).Return(&Hold{ID: 3, LoanID: 1, Reason: "disputed"}, nil)
+
+		_, err := service.RemoveScheduleAdjustment(ctx, 1, 2, "ops")
+
+		assert.ErrorIs(t, err, apperrors.ErrLoanOnHold)
+		tx.AssertNotCalled(t, "RemoveScheduleAdjustment", mock.Anything, mock.Anything)
+	})
+
+	t.Run("reports an adjustment removed concurrently", func(t *testing.T) {
+		l, stored := newLoan(t)
+		mockRepo := new(MockRepository)
+		tx := new(MockTxRepository)
+		service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.NewFake(now), logger)
+		mockRepo.On("GetLoanByID", ctx, int64(1)).Return(l, nil)
+		mockRepo.On("WithinTransaction", ctx).Return(tx, nil)
+		tx.On("LockLoanForPayment", ctx, int64(1)).Return(nil)
+		tx.On("GetActiveHold", ctx, int64(1)).Return((*Hold)(nil), nil)
+		tx.On("GetRateHistory", ctx, int64(1)).Return([]RateChange{}, nil)
+		tx.On("GetScheduleAdjustments", ctx, int64(1)).Return(adjustments, nil)
+		tx.On("GetPrepayments", ctx, int64(1)).Return([]Prepayment{}, nil)
+		tx.On("GetScheduleForUpdate", ctx, int64(1)).Return(stored, nil)
+		tx.On("ApplyScheduleChanges", ctx, int64(1), mock.Anything).Return(nil)
+		tx.On("RemoveScheduleAdjustment", ctx, mock.Anything).Return(apperrors.ErrNotFound)
+
+		_, err := service.RemoveScheduleAdjustment(ctx, 1, 2, "ops")
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+
+	t.Run("reports an unknown loan", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.NewFake(now), logger)
+		mockRepo.On("GetLoanByID", ctx, int64(1)).Return((*Loan)(nil), apperrors.ErrNotFound)
+
+		_, err := service.RemoveScheduleAdjustment(ctx, 1, 2, "ops")
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+
+	t.Run("forbids customer scope", func(t *testing.T) {
+		service := NewLoanService(new(MockRepository), new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.NewFake(now), logger)
+
+		_, err := service.RemoveScheduleAdjustment(scope.WithCustomer(ctx, 5), 1, 2, "ops")
+
+		assert.ErrorIs(t, err, apperrors.ErrForbidden)
+	})
+}
+
+func TestPrepay(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 14, 9, 0, 0, 0, time.UTC)
+	// newLoan returns a 300 loan repaid in three weekly installments of 110,
+	// the first of them paid.
+	newLoan := func(t *testing.T) (*Loan, []ScheduleEntry) {
+		l, err := NewLoan(300, 3, 0.1, time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC))
+		require.NoError(t, err)
+		l.ID = 1
+		schedule, err := l.GenerateSchedule()
+		require.NoError(t, err)
+		paidAt := time.Date(2025, 1, 13, 10, 0, 0, 0, time.UTC)
+		schedule[0].Status, schedule[0].PaidAmount, schedule[0].PaymentDate = PaymentStatusPaid, 110, &paidAt
+		return l, schedule
+	}
+	details := PaymentDetails{Channel: ChannelBankTransfer, Reference: "TRX-9"}
+	expectReshape := func(tx *MockTxRepository, stored []ScheduleEntry, hold *Hold) {
+		tx.On("LockLoanForPayment", ctx, int64(1)).Return(nil)
+		tx.On("GetRateHistory", ctx, int64(1)).Return([]RateChange{}, nil)
+		tx.On("GetScheduleAdjustments", ctx, int64(1)).Return([]ScheduleAdjustment{}, nil)
+		tx.On("GetPrepayments", ctx, int64(1)).Return([]Prepayment{}, nil)
+		tx.On("GetScheduleForUpdate", ctx, int64(1)).Return(stored, nil)
+		tx.On("GetActiveHold", ctx, int64(1)).Return(hold, nil)
+	}
+
+	t.Run("reamortizes the schedule and records the prepayment", func(t *testing.T) {
+		l, stored := newLoan(t)
+		mockRepo := new(MockRepository)
+		tx := new(MockTxRepository)
+		service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.NewFake(now), logger)
+		mockRepo.On("GetLoanByID", ctx, int64(1)).Return(l, nil)
+		mockRepo.On("WithinTransaction", ctx).Return(tx, nil)
+		expectReshape(tx, stored, nil)
+		tx.On("ApplyScheduleChanges", ctx, int64(1), mock.MatchedBy(func(changes []ScheduleChange) bool {
+			return len(changes) == 1 && changes[0].Kind == ScheduleEntryRemoved && changes[0].WeekNumber == 3
+		})).Return(nil)
+		tx.On("RecordPrepayment", ctx, mock.MatchedBy(func(r *Reamortization) bool {
+			p := r.Prepayment
+			return p.Option == PrepaymentReduceTerm && p.Amount == 100 && p.TermWeeks == 2 && p.TotalLoanAmount == 320 &&
+				p.Channel == ChannelBankTransfer && *p.Reference == "TRX-9" && *p.RecordedBy == "teller" && p.PaidAt.Equal(now)
+		})).Return(nil)
+
+		plan, err := service.Prepay(ctx, 1, 100, PrepaymentReduceTerm, details, "teller")
+
+		require.NoError(t, err)
+		assert.Equal(t, 110.0, plan.Prepayment.WeeklyPaymentAmount)
+		mockRepo.AssertExpectations(t)
+		tx.AssertExpectations(t)
+	})
+
+	t.Run("refuses a loan on hold", func(t *testing.T) {
+		l, stored := newLoan(t)
+		mockRepo := new(MockRepository)
+		tx := new(MockTxRepository)
+		service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.NewFake(now), logger)
+		mockRepo.On("GetLoanByID", ctx, int64(1)).Return(l, nil)
+		mockRepo.On("WithinTransaction", ctx).Return(tx, nil)
+		expectReshape(tx, stored, &Hold{ID: 3, Reason: "disputed"})
+
+		_, err := service.Prepay(ctx, 1, 100, PrepaymentReduceTerm, details, "teller")
+
+		assert.ErrorIs(t, err, apperrors.ErrLoanOnHold)
+		tx.AssertNotCalled(t, "ApplyScheduleChanges", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("reports a reference already posted", func(t *testing.T) {
+		l, stored := newLoan(t)
+		mockRepo := new(MockRepository)
+		tx := new(MockTxRepository)
+		service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.NewFake(now), logger)
+		mockRepo.On("GetLoanByID", ctx, int64(1)).Return(l, nil)
+		mockRepo.On("WithinTransaction", ctx).Return(tx, nil)
+		expectReshape(tx, stored, nil)
+		tx.On("ApplyScheduleChanges", ctx, int64(1), mock.Anything).Return(nil)
+		tx.On("RecordPrepayment", ctx, mock.Anything).Return(apperrors.ErrAlreadyExists)
+
+		_, err := service.Prepay(ctx, 1, 50, PrepaymentReduceInstallment, details, "teller")
+
+		assert.ErrorIs(t, err, apperrors.ErrAlreadyExists)
+	})
+
+	t.Run("reports a failed write as internal", func(t *testing.T) {
+		l, stored := newLoan(t)
+		mockRepo := new(MockRepository)
+		tx := new(MockTxRepository)
+		service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.NewFake(now), logger)
+		mockRepo.On("GetLoanByID", ctx, int64(1)).Return(l, nil)
+		mockRepo.On("WithinTransaction", ctx).Return(tx, nil)
+		expectReshape(tx, stored, nil)
+		tx.On("ApplyScheduleChanges", ctx, int64(1), mock.Anything).Return(apperrors.ErrDatabase)
+
+		_, err := service.Prepay(ctx, 1, 50, PrepaymentReduceInstallment, details, "teller")
+
+		assert.ErrorIs(t, err, apperrors.ErrInternalServer)
+		tx.AssertNotCalled(t, "RecordPrepayment", mock.Anything, mock.Anything)
+	})
+
+	t.Run("validates before reading the loan", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.NewFake(now), logger)
+
+		_, err := service.Prepay(ctx, 1, 50, "SKIP", details, "teller")
+		assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+
+		_, err = service.Prepay(ctx, 1, 50, PrepaymentReduceTerm, PaymentDetails{Channel: "CHEQUE"}, "teller")
+		assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+		mockRepo.AssertNotCalled(t, "GetLoanByID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("forbids customer scope", func(t *testing.T) {
+		service := NewLoanService(new(MockRepository), new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.NewFake(now), logger)
+
+		_, err := service.Prepay(scope.WithCustomer(ctx, 5), 1, 50, PrepaymentReduceTerm, details, "teller")
+
+		assert.ErrorIs(t, err, apperrors.ErrForbidden)
+	})
+}
+
+func TestPreviewRestructure(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 14, 9, 0, 0, 0, time.UTC)
+	prepayment := Restructure{Kind: RestructurePrepayment, Amount: 100, Option: PrepaymentReduceTerm}
+
+	t.Run("writes nothing", func(t *testing.T) {
+		l, err := NewLoan(300, 3, 0.1, time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC))
+		require.NoError(t, err)
+		l.ID = 1
+		stored, err := l.GenerateSchedule()
+		require.NoError(t, err)
+		paidAt := time.Date(2025, 1, 13, 10, 0, 0, 0, time.UTC)
+		stored[0].Status, stored[0].PaidAmount, stored[0].PaymentDate = PaymentStatusPaid, 110, &paidAt
+		mockRepo := new(MockRepository)
+		service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.NewFake(now), logger)
+		mockRepo.On("GetLoanByID", ctx, int64(1)).Return(l, nil)
+		mockRepo.On("GetScheduleByLoanID", ctx, int64(1)).Return(stored, nil)
+		mockRepo.On("GetRateHistory", ctx, int64(1)).Return([]RateChange{}, nil)
+		mockRepo.On("GetScheduleAdjustments", ctx, int64(1)).Return([]ScheduleAdjustment{}, nil)
+		mockRepo.On("GetPrepayments", ctx, int64(1)).Return([]Prepayment{}, nil)
+		mockRepo.On("GetActiveHold", ctx, int64(1)).Return(nil, nil)
+
+		preview, err := service.PreviewRestructure(ctx, 1, prepayment)
+
+		require.NoError(t, err)
+		assert.Equal(t, 330.0, preview.Before.TotalLoanAmount)
+		assert.Equal(t, 320.0, preview.After.TotalLoanAmount)
+		mockRepo.AssertExpectations(t)
+		// Without a transaction it takes neither the payment lock nor a
+		// FOR UPDATE read, so a payment running alongside is not refused.
+		mockRepo.AssertNotCalled(t, "WithinTransaction", mock.Anything)
+	})
+
+	t.Run("refuses a loan on hold whatever the change", func(t *testing.T) {
+		l, err := NewLoan(300, 3, 0.1, time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC))
+		require.NoError(t, err)
+		l.ID = 1
+		stored, err := l.GenerateSchedule()
+		require.NoError(t, err)
+		mockRepo := new(MockRepository)
+		service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.NewFake(now), logger)
+		mockRepo.On("GetLoanByID", ctx, int64(1)).Return(l, nil)
+		mockRepo.On("GetScheduleByLoanID", ctx, int64(1)).Return(stored, nil)
+		mockRepo.On("GetRateHistory", ctx, int64(1)).Return([]RateChange{}, nil)
+		mockRepo.On("GetScheduleAdjustments", ctx, int64(1)).Return([]ScheduleAdjustment{}, nil)
+		mockRepo.On("GetPrepayments", ctx, int64(1)).Return([]Prepayment{}, nil)
+		mockRepo.On("GetActiveHold", ctx, int64(1)).Return(&Hold{ID: 3, LoanID: 1, Reason: "disputed"}, nil)
+
+		_, err = service.PreviewRestructure(ctx, 1, Restructure{Kind: RestructureRepricing, Rate: 0.16, EffectiveFrom: time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC)})
+
+		assert.ErrorIs(t, err, apperrors.ErrLoanOnHold)
+	})
+
+	t.Run("validates before reading the loan", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.NewFake(now), logger)
+
+		_, err := service.PreviewRestructure(ctx, 1, Restructure{Kind: RestructurePaymentHoliday, StartsOn: now, Weeks: 60})
+
+		assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+		mockRepo.AssertNotCalled(t, "GetLoanByID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("is staff only", func(t *testing.T) {
+		service := NewLoanService(new(MockRepository), new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.NewFake(now), logger)
+
+		_, err := service.PreviewRestructure(scope.WithCustomer(ctx, 5), 1, prepayment)
+
+		assert.ErrorIs(t, err, apperrors.ErrForbidden)
+	})
+}
+
+func TestPostFee(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 14, 9, 0, 0, 0, time.UTC)
+	newService := func(l *Loan) (LoanService, *MockRepository) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetLoanByID", ctx, int64(1)).Return(l, nil).Maybe()
+		return NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.NewFake(now), logger), mockRepo
+	}
+
+	t.Run("posts a catalog fee", func(t *testing.T) {
+		service, mockRepo := newService(&Loan{ID: 1, Status: StatusActive})
+		mockRepo.On("PostFee", ctx, mock.MatchedBy(func(f *Fee) bool {
+			return f.LoanID == 1 && f.Type == FeeBounce && f.Amount == 25.13 && f.Reason == "direct debit returned" &&
+				*f.PostedBy == "teller" && f.PostedAt.Equal(now)
+		})).Run(func(args mock.Arguments) { args.Get(1).(*Fee).ID = 4 }).Return(nil)
+
+		fee, err := service.PostFee(ctx, 1, FeeBounce, 25.129, "  direct debit returned ", "teller")
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(4), fee.ID)
+		assert.NotNil(t, fee.Waivers)
+		assert.Nil(t, fee.Tax, "no tax is configured")
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("charges the configured tax", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetLoanByID", ctx, int64(1)).Return(&Loan{ID: 1, Status: StatusActive}, nil)
+		taxes, err := NewTaxPolicy("id", map[string]TaxRates{"id": {Fees: map[FeeType]float64{"bounce": 0.11}}})
+		require.NoError(t, err)
+		service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), taxes, CreditPolicy{}, ProductCatalog{}, clock.NewFake(now), logger)
+		mockRepo.On("PostFee", ctx, mock.Anything).Return(nil).Twice()
+
+		fee, err := service.PostFee(ctx, 1, FeeBounce, 25.13, "direct debit returned", "teller")
+
+		require.NoError(t, err)
+		assert.Equal(t, &TaxLine{LoanID: 1, Jurisdiction: "ID", Rate: 0.11, TaxableAmount: 25.13, Amount: 2.76, CreatedAt: now}, fee.Tax)
+
+		fee, err = service.PostFee(ctx, 1, FeeLegal, 50, "court filing", "teller")
+
+		require.NoError(t, err)
+		assert.Nil(t, fee.Tax, "legal fees are not taxed")
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("validates the fee", func(t *testing.T) {
+		service, mockRepo := newService(&Loan{ID: 1, Status: StatusActive})
+		for name, call := range map[string]func() error{
+			"unknown type": func() error { _, err := service.PostFee(ctx, 1, "PENALTY", 25, "late", ""); return err },
+			"zero amount":  func() error { _, err := service.PostFee(ctx, 1, FeeLegal, 0.001, "court filing", ""); return err },
+			"no reason":    func() error { _, err := service.PostFee(ctx, 1, FeeLegal, 50, " ", ""); return err },
+		} {
+			assert.ErrorIs(t, call(), apperrors.ErrInvalidArgument, name)
+		}
+		mockRepo.AssertNotCalled(t, "PostFee", mock.Anything, mock.Anything)
+	})
+
+	t.Run("refuses a paid-off loan", func(t *testing.T) {
+		service, mockRepo := newService(&Loan{ID: 1, Status: StatusPaidOff})
+
+		_, err := service.PostFee(ctx, 1, FeeLegal, 50, "court filing", "")
+
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+		mockRepo.AssertNotCalled(t, "PostFee", mock.Anything, mock.Anything)
+	})
+
+	t.Run("refuses customer tokens", func(t *testing.T) {
+		service, _ := newService(&Loan{ID: 1, Status: StatusActive})
+
+		_, err := service.PostFee(scope.WithCustomer(ctx, 5), 1, FeeLegal, 50, "court filing", "")
+
+		assert.ErrorIs(t, err, apperrors.ErrForbidden)
+	})
+}
+
+func TestChargeLateFees(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 30, 9, 0, 0, 0, time.UTC)
+	policy := DelinquencyPolicy{MissedPayments: 2, CurePayments: 2, GraceDays: 3, LateFee: 15}
+	paidOn := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	// Week 1 is paid, weeks 2 and 3 are past their grace period and week 4
+	// is still in it.
+	schedule := []ScheduleEntry{
+		{WeekNumber: 1, DueDate: time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC), Status: PaymentStatusPaid, PaymentDate: &paidOn},
+		{WeekNumber: 2, DueDate: time.Date(2025, 1, 13, 0, 0, 0, 0, time.UTC), Status: PaymentStatusPending},
+		{WeekNumber: 3, DueDate: time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC), Status: PaymentStatusPending},
+		{WeekNumber: 4, DueDate: time.Date(2025, 1, 27, 0, 0, 0, 0, time.UTC), Status: PaymentStatusPending},
+	}
+	newService := func(l *Loan) (LoanService, *MockRepository) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetLoanByID", ctx, int64(1)).Return(l, nil).Maybe()
+		return NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.NewFake(now), logger), mockRepo
+	}
+
+	t.Run("charges each late installment once", func(t *testing.T) {
+		service, mockRepo := newService(&Loan{ID: 1, Status: StatusActive})
+		mockRepo.On("GetActiveHold", ctx, int64(1)).Return((*Hold)(nil), nil)
+		mockRepo.On("GetScheduleByLoanID", ctx, int64(1)).Return(schedule, nil)
+		mockRepo.On("GetFees", ctx, int64(1)).Return([]Fee{
+			{ID: 3, LoanID: 1, Type: FeeLate, Amount: 15, Reason: "Installment 2 due 2025-01-13 unpaid after the grace period"},
+		}, nil)
+		mockRepo.On("PostFee", ctx, mock.MatchedBy(func(f *Fee) bool {
+			return f.LoanID == 1 && f.Type == FeeLate && f.Amount == 15 && f.PostedBy == nil && f.PostedAt.Equal(now) &&
+				f.Reason == "Installment 3 due 2025-01-20 unpaid after the grace period"
+		})).Return(nil).Once()
+
+		fees, err := service.ChargeLateFees(ctx, 1, policy)
+
+		require.NoError(t, err)
+		require.Len(t, fees, 1)
+		assert.Equal(t, FeeLate, fees[0].Type)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("skips a loan on hold", func(t *testing.T) {
+		service, mockRepo := newService(&Loan{ID: 1, Status: StatusActive})
+		mockRepo.On("GetActiveHold", ctx, int64(1)).Return(&Hold{ID: 4, LoanID: 1, Reason: "fraud review"}, nil)
+
+		fees, err := service.ChargeLateFees(ctx, 1, policy)
+
+		assert.ErrorIs(t, err, apperrors.ErrLoanOnHold)
+		assert.Empty(t, fees)
+		mockRepo.AssertNotCalled(t, "PostFee", mock.Anything, mock.Anything)
+	})
+
+	t.Run("charges nothing without a late fee", func(t *testing.T) {
+		service, mockRepo := newService(&Loan{ID: 1, Status: StatusActive})
+
+		fees, err := service.ChargeLateFees(ctx, 1, DefaultDelinquencyPolicy())
+
+		require.NoError(t, err)
+		assert.Empty(t, fees)
+		mockRepo.AssertNotCalled(t, "GetLoanByID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("charges nothing on a loan that is not active", func(t *testing.T) {
+		service, mockRepo := newService(&Loan{ID: 1, Status: StatusPaidOff})
+
+		fees, err := service.ChargeLateFees(ctx, 1, policy)
+
+		require.NoError(t, err)
+		assert.Empty(t, fees)
+		mockRepo.AssertNotCalled(t, "PostFee", mock.Anything, mock.Anything)
+	})
+
+	t.Run("refuses customer tokens", func(t *testing.T) {
+		service, _ := newService(&Loan{ID: 1, Status: StatusActive})
+
+		_, err := service.ChargeLateFees(scope.WithCustomer(ctx, 5), 1, policy)
+
+		assert.ErrorIs(t, err, apperrors.ErrForbidden)
+	})
+}
+
+func TestTaxReport(t *testing.T) {
+	mockRepo := new(MockRepository)
+	service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
+	ctx := context.Background()
+	from := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)
+
+	t.Run("totals the tax lines", func(t *testing.T) {
+		mockRepo.On("SumTaxLines", ctx, from, to.AddDate(0, 0, 1)).Return([]TaxTotals{
+			{Jurisdiction: "ID", FeeType: FeeBounce, Lines: 2, TaxableAmount: 50, Amount: 5.5, WaivedAmount: 2.75},
+			{Jurisdiction: "ID", FeeType: FeeLegal, Lines: 1, TaxableAmount: 100, Amount: 11.004},
+		}, nil).Once()
+
+		report, err := service.TaxReport(ctx, from, to.Add(15*time.Hour))
+
+		require.NoError(t, err)
+		assert.Equal(t, from, report.From)
+		assert.Equal(t, to, report.To)
+		require.Len(t, report.Rows, 2)
+		assert.Equal(t, Money(11), report.Rows[1].Amount)
+		assert.Equal(t, Money(16.5), report.TotalAmount)
+		assert.Equal(t, Money(2.75), report.TotalWaived)
+		assert.Equal(t, Money(13.75), report.TotalPayable)
+	})
+
+	t.Run("rejects an inverted range", func(t *testing.T) {
+		_, err := service.TaxReport(ctx, to, from)
+
+		assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+	})
+
+	t.Run("forbids customer scope", func(t *testing.T) {
+		_, err := service.TaxReport(scope.WithCustomer(ctx, 5), from, to)
+
+		assert.ErrorIs(t, err, apperrors.ErrForbidden)
+	})
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestFeeWaivers(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 14, 9, 0, 0, 0, time.UTC)
+	teller := "teller"
+	newService := func(fees []Fee) (LoanService, *MockRepository) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetFees", ctx, int64(1)).Return(fees, nil)
+		return NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.NewFake(now), logger), mockRepo
+	}
+	fee := func(waivers ...FeeWaiver) []Fee {
+		return []Fee{{ID: 4, LoanID: 1, Type: FeeBounce, Amount: 25, Reason: "direct debit returned", Waivers: waivers}}
+	}
+	pending := FeeWaiver{ID: 9, FeeID: 4, LoanID: 1, Reason: "bank error", Status: WaiverPending, RequestedBy: &teller}
+
+	t.Run("requests a waiver", func(t *testing.T) {
+		service, mockRepo := newService(fee(FeeWaiver{ID: 8, FeeID: 4, Status: WaiverRejected}))
+		mockRepo.On("RequestFeeWaiver", ctx, mock.MatchedBy(func(w *FeeWaiver) bool {
+			return w.FeeID == 4 && w.LoanID == 1 && w.Status == WaiverPending && w.Reason == "bank error" &&
+				*w.RequestedBy == "teller" && w.RequestedAt.Equal(now)
+		})).Return(nil)
+
+		waiver, err := service.RequestFeeWaiver(ctx, 1, 4, "bank error", "teller")
+
+		require.NoError(t, err)
+		assert.Equal(t, WaiverPending, waiver.Status)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("refuses a second open waiver", func(t *testing.T) {
+		for name, waivers := range map[string][]FeeWaiver{
+			"pending":  {pending},
+			"approved": {{ID: 9, FeeID: 4, Status: WaiverApproved}},
+		} {
+			service, mockRepo := newService(fee(waivers...))
+
+			_, err := service.RequestFeeWaiver(ctx, 1, 4, "bank error", "teller")
+
+			assert.ErrorIs(t, err, apperrors.ErrConflict, name)
+			mockRepo.AssertNotCalled(t, "RequestFeeWaiver", mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("reports an unknown fee", func(t *testing.T) {
+		service, _ := newService(fee())
+
+		_, err := service.RequestFeeWaiver(ctx, 1, 5, "bank error", "teller")
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+
+	t.Run("approves a pending waiver", func(t *testing.T) {
+		service, mockRepo := newService(fee(pending))
+		mockRepo.On("DecideFeeWaiver", ctx, mock.MatchedBy(func(w *FeeWaiver) bool {
+			return w.ID == 9 && w.Status == WaiverApproved && *w.DecidedBy == "supervisor" && w.DecidedAt.Equal(now)
+		})).Return(nil)
+		tx := new(MockTxRepository)
+		mockRepo.On("WithinTransaction", ctx).Return(tx, nil)
+		tx.On("CheckIfAllPaymentsMade", ctx, int64(1)).Return(false, nil)
+
+		waiver, err := service.DecideFeeWaiver(ctx, 1, 4, true, "supervisor")
+
+		require.NoError(t, err)
+		assert.Equal(t, WaiverApproved, waiver.Status)
+		tx.AssertNotCalled(t, "UpdateLoanStatus", mock.Anything, mock.Anything, mock.Anything)
+		mockRepo.AssertExpectations(t)
+		tx.AssertExpectations(t)
+	})
+
+	t.Run("pays the loan off when the waiver clears the last amount owed", func(t *testing.T) {
+		service, mockRepo := newService(fee(pending))
+		mockRepo.On("DecideFeeWaiver", ctx, mock.Anything).Return(nil)
+		tx := new(MockTxRepository)
+		mockRepo.On("WithinTransaction", ctx).Return(tx, nil)
+		tx.On("CheckIfAllPaymentsMade", ctx, int64(1)).Return(true, nil)
+		tx.On("UpdateLoanStatus", ctx, int64(1), StatusPaidOff).Return(nil)
+
+		_, err := service.DecideFeeWaiver(ctx, 1, 4, true, "supervisor")
+
+		require.NoError(t, err)
+		tx.AssertExpectations(t)
+	})
+
+	t.Run("refuses to waive a paid fee", func(t *testing.T) {
+		fees := fee()
+		paidAt := now.Add(-time.Hour)
+		fees[0].PaidAt = &paidAt
+		service, mockRepo := newService(fees)
+
+		_, err := service.RequestFeeWaiver(ctx, 1, 4, "bank error", "teller")
+
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+		mockRepo.AssertNotCalled(t, "RequestFeeWaiver", mock.Anything, mock.Anything)
+	})
+
+	t.Run("rejects a pending waiver", func(t *testing.T) {
+		service, mockRepo := newService(fee(pending))
+		mockRepo.On("DecideFeeWaiver", ctx, mock.MatchedBy(func(w *FeeWaiver) bool { return w.Status == WaiverRejected })).Return(nil)
+
+		waiver, err := service.DecideFeeWaiver(ctx, 1, 4, false, "supervisor")
+
+		require.NoError(t, err)
+		assert.Equal(t, WaiverRejected, waiver.Status)
+	})
+
+	t.Run("keeps the requester from deciding", func(t *testing.T) {
+		service, mockRepo := newService(fee(pending))
+
+		_, err := service.DecideFeeWaiver(ctx, 1, 4, true, "teller")
+
+		assert.ErrorIs(t, err, apperrors.ErrForbidden)
+		mockRepo.AssertNotCalled(t, "DecideFeeWaiver", mock.Anything, mock.Anything)
+	})
+
+	t.Run("refuses a decision it cannot check", func(t *testing.T) {
+		anonymous := pending
+		anonymous.RequestedBy = nil
+		for name, tc := range map[string]struct {
+			waiver    FeeWaiver
+			decidedBy string
+		}{
+			"unnamed approver":  {pending, ""},
+			"unknown requester": {anonymous, "supervisor"},
+		} {
+			service, mockRepo := newService(fee(tc.waiver))
+
+			_, err := service.DecideFeeWaiver(ctx, 1, 4, true, tc.decidedBy)
+
+			assert.ErrorIs(t, err, apperrors.ErrForbidden, name)
+			mockRepo.AssertNotCalled(t, "DecideFeeWaiver", mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("refuses an unnamed request", func(t *testing.T) {
+		service, mockRepo := newService(fee())
+
+		_, err := service.RequestFeeWaiver(ctx, 1, 4, "bank error", "")
+
+		assert.ErrorIs(t, err, apperrors.ErrForbidden)
+		mockRepo.AssertNotCalled(t, "RequestFeeWaiver", mock.Anything, mock.Anything)
+	})
+
+	t.Run("needs a pending waiver", func(t *testing.T) {
+		service, _ := newService(fee())
+
+		_, err := service.DecideFeeWaiver(ctx, 1, 4, true, "supervisor")
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+}
+
+func TestSearchLoans(t *testing.T) {
+	ctx := context.Background()
+	from := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	amount := Money(110.5)
+
+	t.Run("fills in the tolerance and limit", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
+		tolerance := Money(1.11)
+		want := LoanSearch{Amount: &amount, Tolerance: &tolerance, From: from, To: from.AddDate(0, 0, 7), CustomerName: "Doe", Limit: DefaultSearchLimit}
+		matches := []SearchMatch{{LoanID: 1}}
+		mockRepo.On("SearchLoans", ctx, want).Return(matches, nil)
+
+		result, err := service.SearchLoans(ctx, LoanSearch{Amount: &amount, From: from, To: from.AddDate(0, 0, 7), CustomerName: " Doe "})
+
+		require.NoError(t, err)
+		assert.Equal(t, matches, result)
+	})
+
+	for name, q := range map[string]LoanSearch{
+		"no criteria":             {},
+		"short name":              {CustomerName: "Jo"},
+		"amount without a window": {Amount: &amount},
+		"window over a month":     {Amount: &amount, From: from, To: from.AddDate(0, 1, 1)},
+		"window without payments": {CustomerName: "Doe", From: from, To: from.AddDate(0, 0, 7)},
+		"window ending first":     {Reference: "TRF-1", From: from, To: from},
+		"limit over the maximum":  {Reference: "TRF-1", Limit: MaxSearchLimit + 1},
+	} {
+		t.Run("rejects "+name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
+
+			_, err := service.SearchLoans(ctx, q)
+
+			assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+			mockRepo.AssertNotCalled(t, "SearchLoans", mock.Anything, mock.Anything)
+		})
+	}
+
+	t.Run("refuses customers", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service := NewLoanService(mockRepo, new(MockCustomerService), DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, ProductCatalog{}, clock.System(), logger)
+
+		_, err := service.SearchLoans(scope.WithCustomer(ctx, 42), LoanSearch{Reference: "TRF-1"})
+
+		assert.ErrorIs(t, err, apperrors.ErrForbidden)
+	})
+}
@@ -0,0 +1,254 @@
+package loan
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+)
+
+const (
+	snapshotDateLayout = "2006-01-02"
+
+	// MaxHistoryDays bounds a single history request.
+	MaxHistoryDays = 366
+)
+
+// Snapshot is the state of one loan at the end of a day.
+type Snapshot struct {
+	LoanID      int64
+	Date        time.Time
+	Status      LoanStatus
+	Outstanding Money
+	DPD         int
+}
+
+// PortfolioRow aggregates the snapshots of one day that share a status and a
+// days-past-due value.
+type PortfolioRow struct {
+	Status      LoanStatus
+	DPD         int
+	Loans       int
+	Outstanding Money
+}
+
+type PortfolioBucket struct {
+	Name        string
+	Loans       int
+	Outstanding Money
+}
+
+// PortfolioReport describes the book on AsOf, the latest snapshot date on or
+// before the requested date.
+type PortfolioReport struct {
+	RequestedDate time.Time
+	AsOf          time.Time
+	Loans         int
+	Outstanding   Money
+	ByStatus      []PortfolioBucket
+	ByDPD         []PortfolioBucket
+}
+
+var dpdBuckets = []struct {
+	name     string
+	min, max int
+}{
+	{"current", 0, 0},
+	{"1-30", 1, 30},
+	{"31-60", 31, 60},
+	{"61-90", 61, 90},
+	{"90+", 91, int(^uint(0) >> 1)},
+}
+
+// DPDBuckets names the days-past-due buckets of the portfolio report, from
+// current to the oldest.
+func DPDBuckets() []string {
+	names := make([]string, len(dpdBuckets))
+	for i, b := range dpdBuckets {
+		names[i] = b.name
+	}
+	return names
+}
+
+// DPDBucket returns the name of the bucket days past due falls in.
+func DPDBucket(days int) string {
+	for _, b := range dpdBuckets {
+		if days >= b.min && days <= b.max {
+			return b.name
+		}
+	}
+	return dpdBuckets[0].name
+}
+
+type SnapshotRepository interface {
+	// WriteDailySnapshots records every loan as of date. Running it twice for
+	// the same date overwrites the earlier rows.
+	WriteDailySnapshots(ctx context.Context, date time.Time) (int64, error)
+
+	GetSnapshots(ctx context.Context, loanID int64, from, to time.Time) ([]Snapshot, error)
+
+	// LatestSnapshotDate returns false when no snapshot exists on or before
+	// date.
+	LatestSnapshotDate(ctx context.Context, onOrBefore time.Time) (time.Time, bool, error)
+
+	GetPortfolioRows(ctx context.Context, date time.Time) ([]PortfolioRow, error)
+
+	// StreamSnapshots calls fn for the snapshot of every loan with an ID above
+	// afterLoanID taken on date, in loan ID order, while the rows are read.
+	StreamSnapshots(ctx context.Context, date time.Time, afterLoanID int64, fn func(Snapshot) error) error
+}
+
+type SnapshotService interface {
+	TakeSnapshots(ctx context.Context, date time.Time) (int64, error)
+	LoanHistory(ctx context.Context, loanID int64, from, to time.Time) ([]Snapshot, error)
+	PortfolioAt(ctx context.Context, date time.Time) (*PortfolioReport, error)
+	// StreamPortfolio hands fn the per-loan snapshots behind PortfolioAt,
+	// starting after the loan ID afterLoanID.
+	StreamPortfolio(ctx context.Context, date time.Time, afterLoanID int64, fn func(Snapshot) error) error
+}
+
+var _ SnapshotService = (*snapshotService)(nil)
+
+type snapshotService struct {
+	repo   SnapshotRepository
+	loans  Repository
+	logger *slog.Logger
+}
+
+func NewSnapshotService(repo SnapshotRepository, loans Repository, logger *slog.Logger) SnapshotService {
+	if repo == nil || loans == nil {
+		panic("snapshot and loan repositories cannot be nil")
+	}
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+		logger.Warn("Warning: No logger provided to NewSnapshotService, using default stderr handler")
+	}
+	return &snapshotService{repo: repo, loans: loans, logger: logger.With(slog.String("component", "snapshotService"))}
+}
+
+func (s *snapshotService) TakeSnapshots(ctx context.Context, date time.Time) (int64, error) {
+	date = truncateToDate(date)
+	written, err := s.repo.WriteDailySnapshots(ctx, date)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed to write loan snapshots", slog.String("date", date.Format(snapshotDateLayout)), slog.Any("error", err))
+		return 0, fmt.Errorf("failed to write loan snapshots for %s: %w", date.Format(snapshotDateLayout), err)
+	}
+	s.logger.InfoContext(ctx, "Loan snapshots written", slog.String("date", date.Format(snapshotDateLayout)), slog.Int64("loans", written))
+	return written, nil
+}
+
+func (s *snapshotService) LoanHistory(ctx context.Context, loanID int64, from, to time.Time) ([]Snapshot, error) {
+	if err := denyCustomerScope(ctx); err != nil {
+		return nil, err
+	}
+	from, to = truncateToDate(from), truncateToDate(to)
+	if from.After(to) {
+		return nil, fmt.Errorf("%w: from must not be after to", apperrors.ErrInvalidArgument)
+	}
+	if to.Sub(from) > MaxHistoryDays*24*time.Hour {
+		return nil, fmt.Errorf("%w: history range cannot exceed %d days", apperrors.ErrInvalidArgument, MaxHistoryDays)
+	}
+
+	snapshots, err := s.repo.GetSnapshots(ctx, loanID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get history of loan %d: %w", loanID, err)
+	}
+	if len(snapshots) == 0 {
+		// Tell an unknown loan apart from a loan without snapshots in range.
+		if _, err := s.loans.GetLoanByID(ctx, loanID); err != nil {
+			if errors.Is(err, apperrors.ErrNotFound) {
+				return nil, fmt.Errorf("%w: loan with ID %d not found", apperrors.ErrNotFound, loanID)
+			}
+			return nil, fmt.Errorf("failed to get loan %d: %w", loanID, err)
+		}
+	}
+	return snapshots, nil
+}
+
+func (s *snapshotService) PortfolioAt(ctx context.Context, date time.Time) (*PortfolioReport, error) {
+	if err := denyCustomerScope(ctx); err != nil {
+		return nil, err
+	}
+	date = truncateToDate(date)
+
+	asOf, ok, err := s.repo.LatestSnapshotDate(ctx, date)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find snapshot date: %w", err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: no loan snapshot exists on or before %s", apperrors.ErrNotFound, date.Format(snapshotDateLayout))
+	}
+
+	rows, err := s.repo.GetPortfolioRows(ctx, asOf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get portfolio for %s: %w", asOf.Format(snapshotDateLayout), err)
+	}
+	return buildPortfolioReport(date, asOf, rows), nil
+}
+
+func (s *snapshotService) StreamPortfolio(ctx context.Context, date time.Time, afterLoanID int64, fn func(Snapshot) error) error {
+	if err := denyCustomerScope(ctx); err != nil {
+		return err
+	}
+	if afterLoanID < 0 {
+		return fmt.Errorf("%w: cursor must not be negative", apperrors.ErrInvalidArgument)
+	}
+	date = truncateToDate(date)
+
+	asOf, ok, err := s.repo.LatestSnapshotDate(ctx, date)
+	if err != nil {
+		return fmt.Errorf("failed to find snapshot date: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("%w: no loan snapshot exists on or before %s", apperrors.ErrNotFound, date.Format(snapshotDateLayout))
+	}
+
+	if err := s.repo.StreamSnapshots(ctx, asOf, afterLoanID, fn); err != nil {
+		return fmt.Errorf("failed to stream portfolio for %s: %w", asOf.Format(snapshotDateLayout), err)
+	}
+	return nil
+}
+
+func buildPortfolioReport(requested, asOf time.Time, rows []PortfolioRow) *PortfolioReport {
+	report := &PortfolioReport{RequestedDate: requested, AsOf: asOf}
+
+	statusIndex := map[LoanStatus]int{}
+	for _, status := range []LoanStatus{StatusActive, StatusDelinquent, StatusPaidOff} {
+		statusIndex[status] = len(report.ByStatus)
+		report.ByStatus = append(report.ByStatus, PortfolioBucket{Name: string(status)})
+	}
+	for _, b := range dpdBuckets {
+		report.ByDPD = append(report.ByDPD, PortfolioBucket{Name: b.name})
+	}
+
+	for _, row := range rows {
+		report.Loans += row.Loans
+		report.Outstanding += row.Outstanding
+
+		i, ok := statusIndex[row.Status]
+		if !ok {
+			i = len(report.ByStatus)
+			statusIndex[row.Status] = i
+			report.ByStatus = append(report.ByStatus, PortfolioBucket{Name: string(row.Status)})
+		}
+		report.ByStatus[i].Loans += row.Loans
+		report.ByStatus[i].Outstanding += row.Outstanding
+
+		for j, b := range dpdBuckets {
+			if row.DPD >= b.min && row.DPD <= b.max {
+				report.ByDPD[j].Loans += row.Loans
+				report.ByDPD[j].Outstanding += row.Outstanding
+				break
+			}
+		}
+	}
+	return report
+}
+
+func truncateToDate(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
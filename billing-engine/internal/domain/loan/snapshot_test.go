@@ -0,0 +1,251 @@
+package loan
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/scope"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockSnapshotRepository struct {
+	mock.Mock
+}
+
+func (m *MockSnapshotRepository) WriteDailySnapshots(ctx context.Context, date time.Time) (int64, error) {
+	args := m.Called(ctx, date)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockSnapshotRepository) GetSnapshots(ctx context.Context, loanID int64, from, to time.Time) ([]Snapshot, error) {
+	args := m.Called(ctx, loanID, from, to)
+	snapshots, _ := args.Get(0).([]Snapshot)
+	return snapshots, args.Error(1)
+}
+
+func (m *MockSnapshotRepository) LatestSnapshotDate(ctx context.Context, onOrBefore time.Time) (time.Time, bool, error) {
+	args := m.Called(ctx, onOrBefore)
+	return args.Get(0).(time.Time), args.Bool(1), args.Error(2)
+}
+
+func (m *MockSnapshotRepository) GetPortfolioRows(ctx context.Context, date time.Time) ([]PortfolioRow, error) {
+	args := m.Called(ctx, date)
+	rows, _ := args.Get(0).([]PortfolioRow)
+	return rows, args.Error(1)
+}
+
+func (m *MockSnapshotRepository) StreamSnapshots(ctx context.Context, date time.Time, afterLoanID int64, fn func(Snapshot) error) error {
+	args := m.Called(ctx, date, afterLoanID)
+	snapshots, _ := args.Get(0).([]Snapshot)
+	for _, s := range snapshots {
+		if err := fn(s); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+func day(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestTakeSnapshotsTruncatesToDate(t *testing.T) {
+	repo := new(MockSnapshotRepository)
+	service := NewSnapshotService(repo, new(MockRepository), logger)
+	ctx := context.Background()
+
+	repo.On("WriteDailySnapshots", ctx, day(2025, 3, 31)).Return(int64(12), nil)
+
+	written, err := service.TakeSnapshots(ctx, time.Date(2025, 3, 31, 23, 50, 0, 0, time.UTC))
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(12), written)
+	repo.AssertExpectations(t)
+}
+
+func TestTakeSnapshotsError(t *testing.T) {
+	repo := new(MockSnapshotRepository)
+	service := NewSnapshotService(repo, new(MockRepository), logger)
+	ctx := context.Background()
+
+	repo.On("WriteDailySnapshots", ctx, day(2025, 3, 31)).Return(int64(0), apperrors.ErrDatabase)
+
+	_, err := service.TakeSnapshots(ctx, day(2025, 3, 31))
+
+	assert.ErrorIs(t, err, apperrors.ErrDatabase)
+}
+
+func TestLoanHistory(t *testing.T) {
+	ctx := context.Background()
+	from, to := day(2025, 3, 1), day(2025, 3, 31)
+
+	t.Run("returns snapshots in range", func(t *testing.T) {
+		repo := new(MockSnapshotRepository)
+		service := NewSnapshotService(repo, new(MockRepository), logger)
+		expected := []Snapshot{{LoanID: 7, Date: from, Status: StatusActive, Outstanding: 1000}}
+		repo.On("GetSnapshots", ctx, int64(7), from, to).Return(expected, nil)
+
+		history, err := service.LoanHistory(ctx, 7, from, to)
+
+		require.NoError(t, err)
+		assert.Equal(t, expected, history)
+	})
+
+	t.Run("empty history for existing loan", func(t *testing.T) {
+		repo, loans := new(MockSnapshotRepository), new(MockRepository)
+		service := NewSnapshotService(repo, loans, logger)
+		repo.On("GetSnapshots", ctx, int64(7), from, to).Return(nil, nil)
+		loans.On("GetLoanByID", ctx, int64(7)).Return(&Loan{ID: 7}, nil)
+
+		history, err := service.LoanHistory(ctx, 7, from, to)
+
+		require.NoError(t, err)
+		assert.Empty(t, history)
+	})
+
+	t.Run("unknown loan", func(t *testing.T) {
+		repo, loans := new(MockSnapshotRepository), new(MockRepository)
+		service := NewSnapshotService(repo, loans, logger)
+		repo.On("GetSnapshots", ctx, int64(7), from, to).Return(nil, nil)
+		loans.On("GetLoanByID", ctx, int64(7)).Return((*Loan)(nil), apperrors.ErrNotFound)
+
+		_, err := service.LoanHistory(ctx, 7, from, to)
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+
+	t.Run("rejects inverted and oversized ranges", func(t *testing.T) {
+		service := NewSnapshotService(new(MockSnapshotRepository), new(MockRepository), logger)
+
+		_, err := service.LoanHistory(ctx, 7, to, from)
+		assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+
+		_, err = service.LoanHistory(ctx, 7, day(2023, 1, 1), to)
+		assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+	})
+
+	t.Run("denies customer scope", func(t *testing.T) {
+		service := NewSnapshotService(new(MockSnapshotRepository), new(MockRepository), logger)
+
+		_, err := service.LoanHistory(scope.WithCustomer(ctx, 42), 7, from, to)
+
+		assert.ErrorIs(t, err, apperrors.ErrForbidden)
+	})
+}
+
+func TestPortfolioAt(t *testing.T) {
+	ctx := context.Background()
+	requested, asOf := day(2025, 3, 31), day(2025, 3, 30)
+
+	t.Run("aggregates the latest snapshot", func(t *testing.T) {
+		repo := new(MockSnapshotRepository)
+		service := NewSnapshotService(repo, new(MockRepository), logger)
+		repo.On("LatestSnapshotDate", ctx, requested).Return(asOf, true, nil)
+		repo.On("GetPortfolioRows", ctx, asOf).Return([]PortfolioRow{
+			{Status: StatusActive, DPD: 0, Loans: 5, Outstanding: 5000},
+			{Status: StatusActive, DPD: 10, Loans: 1, Outstanding: 800},
+			{Status: StatusDelinquent, DPD: 45, Loans: 2, Outstanding: 1500},
+			{Status: StatusDelinquent, DPD: 120, Loans: 1, Outstanding: 900},
+			{Status: StatusPaidOff, DPD: 0, Loans: 3, Outstanding: 0},
+		}, nil)
+
+		report, err := service.PortfolioAt(ctx, requested)
+
+		require.NoError(t, err)
+		assert.Equal(t, requested, report.RequestedDate)
+		assert.Equal(t, asOf, report.AsOf)
+		assert.Equal(t, 12, report.Loans)
+		assert.InDelta(t, 8200, report.Outstanding, 0.001)
+		assert.Equal(t, []PortfolioBucket{
+			{Name: "ACTIVE", Loans: 6, Outstanding: 5800},
+			{Name: "DELINQUENT", Loans: 3, Outstanding: 2400},
+			{Name: "PAID_OFF", Loans: 3, Outstanding: 0},
+		}, report.ByStatus)
+		assert.Equal(t, []PortfolioBucket{
+			{Name: "current", Loans: 8, Outstanding: 5000},
+			{Name: "1-30", Loans: 1, Outstanding: 800},
+			{Name: "31-60", Loans: 2, Outstanding: 1500},
+			{Name: "61-90"},
+			{Name: "90+", Loans: 1, Outstanding: 900},
+		}, report.ByDPD)
+	})
+
+	t.Run("no snapshot before date", func(t *testing.T) {
+		repo := new(MockSnapshotRepository)
+		service := NewSnapshotService(repo, new(MockRepository), logger)
+		repo.On("LatestSnapshotDate", ctx, requested).Return(time.Time{}, false, nil)
+
+		_, err := service.PortfolioAt(ctx, requested)
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		repo := new(MockSnapshotRepository)
+		service := NewSnapshotService(repo, new(MockRepository), logger)
+		repo.On("LatestSnapshotDate", ctx, requested).Return(asOf, true, nil)
+		repo.On("GetPortfolioRows", ctx, asOf).Return(nil, errors.New("boom"))
+
+		_, err := service.PortfolioAt(ctx, requested)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestStreamPortfolio(t *testing.T) {
+	ctx := context.Background()
+	requested, asOf := day(2025, 3, 31), day(2025, 3, 30)
+
+	t.Run("streams the latest snapshot after the cursor", func(t *testing.T) {
+		repo := new(MockSnapshotRepository)
+		service := NewSnapshotService(repo, new(MockRepository), logger)
+		repo.On("LatestSnapshotDate", ctx, requested).Return(asOf, true, nil)
+		repo.On("StreamSnapshots", ctx, asOf, int64(7)).Return([]Snapshot{
+			{LoanID: 8, Date: asOf, Status: StatusActive, Outstanding: 100},
+			{LoanID: 9, Date: asOf, Status: StatusDelinquent, Outstanding: 250, DPD: 14},
+		}, nil)
+
+		var got []int64
+		err := service.StreamPortfolio(ctx, requested.Add(15*time.Hour), 7, func(s Snapshot) error {
+			got = append(got, s.LoanID)
+			return nil
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, []int64{8, 9}, got)
+	})
+
+	t.Run("no snapshot before date", func(t *testing.T) {
+		repo := new(MockSnapshotRepository)
+		service := NewSnapshotService(repo, new(MockRepository), logger)
+		repo.On("LatestSnapshotDate", ctx, requested).Return(time.Time{}, false, nil)
+
+		err := service.StreamPortfolio(ctx, requested, 0, func(Snapshot) error { return nil })
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+		repo.AssertNotCalled(t, "StreamSnapshots", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("forbids customer scoped callers", func(t *testing.T) {
+		repo := new(MockSnapshotRepository)
+		service := NewSnapshotService(repo, new(MockRepository), logger)
+
+		err := service.StreamPortfolio(scope.WithCustomer(ctx, 42), requested, 0, func(Snapshot) error { return nil })
+
+		assert.ErrorIs(t, err, apperrors.ErrForbidden)
+	})
+}
+
+func TestDPDBucket(t *testing.T) {
+	assert.Equal(t, []string{"current", "1-30", "31-60", "61-90", "90+"}, DPDBuckets())
+	assert.Equal(t, "current", DPDBucket(0))
+	assert.Equal(t, "1-30", DPDBucket(1))
+	assert.Equal(t, "1-30", DPDBucket(30))
+	assert.Equal(t, "31-60", DPDBucket(31))
+	assert.Equal(t, "90+", DPDBucket(400))
+}
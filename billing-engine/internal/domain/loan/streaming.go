@@ -0,0 +1,64 @@
+package loan
+
+import (
+	"billing-engine/internal/event"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// streamingService relays loan lifecycle events to the event hub after the
+// wrapped service has committed them.
+type streamingService struct {
+	LoanService
+	hub   *event.Hub
+	clock clock.Clock
+}
+
+func NewStreamingLoanService(next LoanService, hub *event.Hub, clk clock.Clock) LoanService {
+	return &streamingService{LoanService: next, hub: hub, clock: clock.OrSystem(clk)}
+}
+
+func (s *streamingService) CreateLoan(ctx context.Context, customerID int64, principal Money, termWeeks int, annualInterestRate Money, startDate time.Time, externalRef string, publicID uuid.UUID, product string) (*Loan, error) {
+	created, err := s.LoanService.CreateLoan(ctx, customerID, principal, termWeeks, annualInterestRate, startDate, externalRef, publicID, product)
+	if err != nil {
+		return nil, err
+	}
+	s.hub.Broadcast(event.TypeLoanCreated, event.LoanCreatedEvent{
+		LoanID:          created.ID,
+		CustomerID:      customerID,
+		PrincipalAmount: created.PrincipalAmount,
+		TermWeeks:       created.TermWeeks,
+		Timestamp:       s.clock.Now().UTC(),
+	})
+	return created, nil
+}
+
+func (s *streamingService) MakePayment(ctx context.Context, loanID int64, amount Money, details PaymentDetails) error {
+	if err := s.LoanService.MakePayment(ctx, loanID, amount, details); err != nil {
+		return err
+	}
+	s.hub.Broadcast(event.TypeLoanPaymentReceived, event.LoanPaymentReceivedEvent{
+		LoanID:    loanID,
+		Amount:    amount,
+		Timestamp: s.clock.Now().UTC(),
+	})
+	return nil
+}
+
+// Prepay is relayed as a payment so that subscribers refreshing on payments,
+// such as the summary projector, see the reamortized loan.
+func (s *streamingService) Prepay(ctx context.Context, loanID int64, amount Money, option PrepaymentOption, details PaymentDetails, recordedBy string) (*Reamortization, error) {
+	plan, err := s.LoanService.Prepay(ctx, loanID, amount, option, details, recordedBy)
+	if err != nil {
+		return nil, err
+	}
+	s.hub.Broadcast(event.TypeLoanPaymentReceived, event.LoanPaymentReceivedEvent{
+		LoanID:    loanID,
+		Amount:    plan.Prepayment.Amount,
+		Timestamp: s.clock.Now().UTC(),
+	})
+	return plan, nil
+}
@@ -0,0 +1,78 @@
+package loan
+
+import (
+	"billing-engine/internal/event"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubLoanService struct {
+	LoanService
+	createErr  error
+	paymentErr error
+}
+
+func (s *stubLoanService) Prepay(_ context.Context, loanID int64, amount Money, _ PrepaymentOption, _ PaymentDetails, _ string) (*Reamortization, error) {
+	if s.paymentErr != nil {
+		return nil, s.paymentErr
+	}
+	return &Reamortization{Prepayment: Prepayment{LoanID: loanID, Amount: amount}}, nil
+}
+
+func (s *stubLoanService) CreateLoan(_ context.Context, _ int64, principal Money, termWeeks int, _ Money, _ time.Time, _ string, _ uuid.UUID, _ string) (*Loan, error) {
+	if s.createErr != nil {
+		return nil, s.createErr
+	}
+	return &Loan{ID: 11, PrincipalAmount: principal, TermWeeks: termWeeks}, nil
+}
+
+func (s *stubLoanService) MakePayment(context.Context, int64, Money, PaymentDetails) error {
+	return s.paymentErr
+}
+
+func TestStreamingLoanService(t *testing.T) {
+	hub := event.NewHub(4, 0, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	sub := hub.Subscribe(nil, 0)
+	defer sub.Close()
+
+	now := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	svc := NewStreamingLoanService(&stubLoanService{}, hub, clock.NewFake(now))
+
+	created, err := svc.CreateLoan(context.Background(), 5, 1000, 10, 0.1, time.Now(), "", uuid.Nil, "")
+	require.NoError(t, err)
+	assert.Equal(t, int64(11), created.ID)
+	env := <-sub.C
+	assert.Equal(t, event.TypeLoanCreated, env.Type)
+	var createdEvent event.LoanCreatedEvent
+	require.NoError(t, json.Unmarshal(env.Payload, &createdEvent))
+	assert.Equal(t, now, createdEvent.Timestamp)
+
+	require.NoError(t, svc.MakePayment(context.Background(), 11, 110, PaymentDetails{}))
+	assert.Equal(t, event.TypeLoanPaymentReceived, (<-sub.C).Type)
+
+	_, err = svc.Prepay(context.Background(), 11, 50, PrepaymentReduceTerm, PaymentDetails{}, "")
+	require.NoError(t, err)
+	env = <-sub.C
+	assert.Equal(t, event.TypeLoanPaymentReceived, env.Type)
+	var paymentEvent event.LoanPaymentReceivedEvent
+	require.NoError(t, json.Unmarshal(env.Payload, &paymentEvent))
+	assert.Equal(t, 50.0, paymentEvent.Amount)
+
+	failing := NewStreamingLoanService(&stubLoanService{createErr: errors.New("db"), paymentErr: errors.New("db")}, hub, clock.System())
+	_, err = failing.CreateLoan(context.Background(), 5, 1000, 10, 0.1, time.Now(), "", uuid.Nil, "")
+	assert.Error(t, err)
+	assert.Error(t, failing.MakePayment(context.Background(), 11, 110, PaymentDetails{}))
+	_, err = failing.Prepay(context.Background(), 11, 50, PrepaymentReduceTerm, PaymentDetails{}, "")
+	assert.Error(t, err)
+	assert.Empty(t, sub.C)
+}
@@ -0,0 +1,218 @@
+package loan
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// ScheduleType is how a loan's installments repay its principal.
+type ScheduleType string
+
+const (
+	// ScheduleStandard repays principal and interest in equal installments.
+	ScheduleStandard ScheduleType = "STANDARD"
+	// ScheduleInterestOnly charges only interest for the first weeks and
+	// repays the principal in equal installments over the rest of the term.
+	ScheduleInterestOnly ScheduleType = "INTEREST_ONLY"
+	// ScheduleBalloon leaves part of the principal to a large last
+	// installment.
+	ScheduleBalloon ScheduleType = "BALLOON"
+)
+
+// ParseScheduleType matches s to a schedule type without regard to case.
+func ParseScheduleType(s string) (ScheduleType, error) {
+	switch t := ScheduleType(strings.ToUpper(strings.TrimSpace(s))); t {
+	case ScheduleStandard, ScheduleInterestOnly, ScheduleBalloon:
+		return t, nil
+	}
+	return "", fmt.Errorf("%w: unknown schedule type %q, want %s, %s or %s",
+		apperrors.ErrInvalidArgument, s, ScheduleStandard, ScheduleInterestOnly, ScheduleBalloon)
+}
+
+// ScheduleStructure is the shape of a loan's schedule, set by its product
+// when the loan is booked. Interest is charged flat as on a standard loan,
+// so the structure only moves principal between installments and leaves
+// TotalLoanAmount as it is. The zero structure is standard.
+type ScheduleStructure struct {
+	Type ScheduleType
+	// InterestOnlyWeeks is how many installments of an interest-only loan,
+	// from the first, repay no principal.
+	InterestOnlyWeeks int
+	// BalloonAmount is the principal a balloon loan leaves to its last
+	// installment, which is due on top of that week's regular one.
+	BalloonAmount Money
+}
+
+// Kind is s.Type, with the zero structure reported as standard.
+func (s ScheduleStructure) Kind() ScheduleType {
+	if s.Type == "" {
+		return ScheduleStandard
+	}
+	return s.Type
+}
+
+func (s ScheduleStructure) structured() bool {
+	return s.Kind() != ScheduleStandard
+}
+
+// applyStructure gives l, as NewLoan made it, the structure s and the
+// regular installment that goes with it: the one after the interest-only
+// weeks, or the one before the balloon.
+func (l *Loan) applyStructure(s ScheduleStructure) error {
+	switch s.Kind() {
+	case ScheduleStandard:
+		if s.InterestOnlyWeeks != 0 || s.BalloonAmount != 0 {
+			return fmt.Errorf("%w: a standard schedule has no interest-only weeks or balloon", apperrors.ErrInvalidArgument)
+		}
+		l.Structure = s
+		return nil
+	case ScheduleInterestOnly:
+		if s.BalloonAmount != 0 {
+			return fmt.Errorf("%w: an interest-only schedule has no balloon", apperrors.ErrInvalidArgument)
+		}
+		if s.InterestOnlyWeeks < 1 || s.InterestOnlyWeeks >= l.TermWeeks {
+			return fmt.Errorf("%w: interest-only weeks must be at least 1 and below the term of %d weeks, got %d",
+				apperrors.ErrInvalidArgument, l.TermWeeks, s.InterestOnlyWeeks)
+		}
+		if l.InterestRate <= 0 {
+			// The interest-only weeks would be due nothing.
+			return fmt.Errorf("%w: an interest-only schedule needs a positive interest rate", apperrors.ErrInvalidArgument)
+		}
+		l.Structure = s
+		amortizing := l.TermWeeks - s.InterestOnlyWeeks
+		l.WeeklyPaymentAmount = roundTo((l.TotalLoanAmount-float64(s.InterestOnlyWeeks)*l.interestOnlyPayment())/float64(amortizing), 2)
+		return nil
+	case ScheduleBalloon:
+		if s.InterestOnlyWeeks != 0 {
+			return fmt.Errorf("%w: a balloon schedule has no interest-only weeks", apperrors.ErrInvalidArgument)
+		}
+		if l.TermWeeks < 2 {
+			return fmt.Errorf("%w: a balloon schedule needs at least 2 weeks", apperrors.ErrInvalidArgument)
+		}
+		s.BalloonAmount = roundTo(s.BalloonAmount, 2)
+		if math.IsNaN(s.BalloonAmount) || s.BalloonAmount <= 0 || s.BalloonAmount >= l.PrincipalAmount {
+			return fmt.Errorf("%w: the balloon must be positive and below the principal of %.2f, got %v",
+				apperrors.ErrInvalidArgument, l.PrincipalAmount, s.BalloonAmount)
+		}
+		l.Structure = s
+		l.WeeklyPaymentAmount = roundTo((l.TotalLoanAmount-s.BalloonAmount)/float64(l.TermWeeks), 2)
+		return nil
+	}
+	return fmt.Errorf("%w: unknown schedule type %q", apperrors.ErrInvalidArgument, s.Type)
+}
+
+// interestOnlyPayment is the installment of an interest-only week: the
+// week's share of the flat interest.
+func (l *Loan) interestOnlyPayment() Money {
+	return roundTo(l.PrincipalAmount*l.InterestRate/float64(l.TermWeeks), 2)
+}
+
+// structuredInstallment is the regular installment of week on a structured
+// loan, and false for a standard one.
+func (l *Loan) structuredInstallment(week int) (Money, bool) {
+	switch l.Structure.Kind() {
+	case ScheduleInterestOnly:
+		if week <= l.Structure.InterestOnlyWeeks {
+			return l.interestOnlyPayment(), true
+		}
+		return l.WeeklyPaymentAmount, true
+	case ScheduleBalloon:
+		return l.WeeklyPaymentAmount, true
+	}
+	return 0, false
+}
+
+// structuredPrincipalOf is the principal installment week of a structured
+// loan repays.
+func (l *Loan) structuredPrincipalOf(week int) Money {
+	s := l.Structure
+	switch s.Kind() {
+	case ScheduleInterestOnly:
+		if week <= s.InterestOnlyWeeks {
+			return 0
+		}
+		return l.PrincipalAmount / float64(l.TermWeeks-s.InterestOnlyWeeks)
+	case ScheduleBalloon:
+		principal := (l.PrincipalAmount - s.BalloonAmount) / float64(l.TermWeeks)
+		if week == l.TermWeeks {
+			principal += s.BalloonAmount
+		}
+		return principal
+	}
+	return l.PrincipalAmount / float64(l.TermWeeks)
+}
+
+// checkReshapable refuses change on a structured loan. Repricing, schedule
+// adjustments and prepayments reamortize the schedule evenly, which would
+// undo its structure.
+func (l *Loan) checkReshapable(change string) error {
+	if l.Structure.structured() {
+		return fmt.Errorf("%w: loan %d has a %s schedule; %s is only available on %s schedules",
+			apperrors.ErrConflict, l.ID, l.Structure.Type, change, ScheduleStandard)
+	}
+	return nil
+}
+
+// Product is the repayment schedule of a loan product. Interest-only
+// products set InterestOnlyWeeks and balloon products BalloonShare, the
+// fraction of the principal left to the last installment: 0.3 is 30%.
+type Product struct {
+	Schedule          ScheduleType
+	InterestOnlyWeeks int
+	BalloonShare      float64
+}
+
+// ProductCatalog is the loan products that can be booked, by name. A loan
+// booked without a product has a standard schedule; the zero catalog
+// offers nothing else.
+type ProductCatalog struct {
+	Products map[string]Product
+}
+
+// NewProductCatalog validates the configured products. Names are matched
+// without regard to case, because configuration keys are lowercased when
+// they are read. Whether an interest-only period fits is checked against
+// each loan's term when it is booked.
+func NewProductCatalog(products map[string]Product) (ProductCatalog, error) {
+	c := ProductCatalog{Products: make(map[string]Product, len(products))}
+	for name, p := range products {
+		name = strings.ToLower(strings.TrimSpace(name))
+		schedule, err := ParseScheduleType(string(p.Schedule))
+		if err != nil {
+			return ProductCatalog{}, fmt.Errorf("product %s: %w", name, err)
+		}
+		p.Schedule = schedule
+		switch {
+		case schedule != ScheduleInterestOnly && p.InterestOnlyWeeks != 0:
+			return ProductCatalog{}, fmt.Errorf("%w: product %s has a %s schedule and no interest-only weeks", apperrors.ErrInvalidArgument, name, schedule)
+		case schedule != ScheduleBalloon && p.BalloonShare != 0:
+			return ProductCatalog{}, fmt.Errorf("%w: product %s has a %s schedule and no balloon", apperrors.ErrInvalidArgument, name, schedule)
+		case schedule == ScheduleInterestOnly && p.InterestOnlyWeeks < 1:
+			return ProductCatalog{}, fmt.Errorf("%w: product %s needs at least 1 interest-only week, got %d", apperrors.ErrInvalidArgument, name, p.InterestOnlyWeeks)
+		case schedule == ScheduleBalloon && (math.IsNaN(p.BalloonShare) || p.BalloonShare <= 0 || p.BalloonShare >= 1):
+			return ProductCatalog{}, fmt.Errorf("%w: balloon share of product %s must be above 0 and below 1, got %v", apperrors.ErrInvalidArgument, name, p.BalloonShare)
+		}
+		c.Products[name] = p
+	}
+	return c, nil
+}
+
+// structureFor is the schedule structure product gives a loan of principal.
+// No product is standard.
+func (c ProductCatalog) structureFor(product string, principal Money) (ScheduleStructure, error) {
+	name := strings.ToLower(strings.TrimSpace(product))
+	if name == "" {
+		return ScheduleStructure{}, nil
+	}
+	p, ok := c.Products[name]
+	if !ok {
+		return ScheduleStructure{}, fmt.Errorf("%w: unknown loan product %q", apperrors.ErrInvalidArgument, product)
+	}
+	return ScheduleStructure{
+		Type:              p.Schedule,
+		InterestOnlyWeeks: p.InterestOnlyWeeks,
+		BalloonAmount:     roundTo(principal*p.BalloonShare, 2),
+	}, nil
+}
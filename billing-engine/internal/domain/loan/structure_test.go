@@ -0,0 +1,244 @@
+package loan
+
+import (
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var structureStart = time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+
+func structuredLoan(t *testing.T, s ScheduleStructure) *Loan {
+	t.Helper()
+	l, err := NewLoan(1000, 10, 0.1, structureStart)
+	require.NoError(t, err)
+	require.NoError(t, l.applyStructure(s))
+	return l
+}
+
+func dueAmounts(schedule []ScheduleEntry) []Money {
+	amounts := make([]Money, len(schedule))
+	for i, entry := range schedule {
+		amounts[i] = entry.DueAmount
+	}
+	return amounts
+}
+
+func TestGenerateScheduleStructured(t *testing.T) {
+	t.Run("charges only interest in the interest-only weeks", func(t *testing.T) {
+		l := structuredLoan(t, ScheduleStructure{Type: ScheduleInterestOnly, InterestOnlyWeeks: 4})
+
+		schedule, err := l.GenerateSchedule()
+
+		require.NoError(t, err)
+		assert.Equal(t, 176.67, l.WeeklyPaymentAmount)
+		assert.Equal(t, []Money{10, 10, 10, 10, 176.67, 176.67, 176.67, 176.67, 176.67, 176.65}, dueAmounts(schedule))
+		assert.Equal(t, 1100.0, l.TotalLoanAmount, "interest is as flat as on a standard loan")
+	})
+
+	t.Run("leaves the balloon to the last installment", func(t *testing.T) {
+		l := structuredLoan(t, ScheduleStructure{Type: ScheduleBalloon, BalloonAmount: 300})
+
+		schedule, err := l.GenerateSchedule()
+
+		require.NoError(t, err)
+		assert.Equal(t, 80.0, l.WeeklyPaymentAmount)
+		assert.Equal(t, []Money{80, 80, 80, 80, 80, 80, 80, 80, 80, 380}, dueAmounts(schedule))
+	})
+
+	t.Run("rebuilds to the same schedule", func(t *testing.T) {
+		for _, s := range []ScheduleStructure{
+			{Type: ScheduleInterestOnly, InterestOnlyWeeks: 9},
+			{Type: ScheduleBalloon, BalloonAmount: 999.99},
+		} {
+			l := structuredLoan(t, s)
+			schedule, err := l.GenerateSchedule()
+			require.NoError(t, err, s)
+
+			plan, err := PlanScheduleRebuild(l, schedule)
+
+			require.NoError(t, err, s)
+			assert.Empty(t, plan.Changes, s)
+		}
+	})
+}
+
+func TestApplyStructureValidates(t *testing.T) {
+	tests := map[string]ScheduleStructure{
+		"no interest-only weeks":               {Type: ScheduleInterestOnly},
+		"interest-only for the whole term":     {Type: ScheduleInterestOnly, InterestOnlyWeeks: 10},
+		"interest-only with a balloon":         {Type: ScheduleInterestOnly, InterestOnlyWeeks: 2, BalloonAmount: 100},
+		"no balloon":                           {Type: ScheduleBalloon},
+		"a balloon of the whole principal":     {Type: ScheduleBalloon, BalloonAmount: 1000},
+		"a balloon with interest-only weeks":   {Type: ScheduleBalloon, InterestOnlyWeeks: 2, BalloonAmount: 100},
+		"a standard schedule with a balloon":   {Type: ScheduleStandard, BalloonAmount: 100},
+		"an unknown type":                      {Type: "STEP_UP"},
+		"a negative balloon":                   {Type: ScheduleBalloon, BalloonAmount: -1},
+		"a balloon that is not a number":       {Type: ScheduleBalloon, BalloonAmount: Money(math.NaN())},
+		"a standard schedule with a deferment": {InterestOnlyWeeks: 2},
+	}
+	for name, s := range tests {
+		t.Run("rejects "+name, func(t *testing.T) {
+			l, err := NewLoan(1000, 10, 0.1, structureStart)
+			require.NoError(t, err)
+
+			assert.ErrorIs(t, l.applyStructure(s), apperrors.ErrInvalidArgument)
+		})
+	}
+
+	t.Run("rejects interest-only weeks without interest", func(t *testing.T) {
+		l, err := NewLoan(1000, 10, 0, structureStart)
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, l.applyStructure(ScheduleStructure{Type: ScheduleInterestOnly, InterestOnlyWeeks: 2}), apperrors.ErrInvalidArgument)
+	})
+}
+
+func TestCalculateOutstandingStructured(t *testing.T) {
+	payWeeks := func(schedule []ScheduleEntry, weeks int) []ScheduleEntry {
+		for i := 0; i < weeks; i++ {
+			schedule[i].PaidAmount, schedule[i].Status = schedule[i].DueAmount, PaymentStatusPaid
+		}
+		return schedule
+	}
+
+	t.Run("interest-only weeks repay no principal", func(t *testing.T) {
+		l := structuredLoan(t, ScheduleStructure{Type: ScheduleInterestOnly, InterestOnlyWeeks: 4})
+		schedule, err := l.GenerateSchedule()
+		require.NoError(t, err)
+
+		b := CalculateOutstanding(l, payWeeks(schedule, 4), nil, structureStart.AddDate(0, 0, 29), DefaultPaymentPolicy(), TaxPolicy{})
+
+		assert.Equal(t, 1060.0, b.Installments)
+		assert.Equal(t, 1000.0, b.Principal, "the whole principal is still owed")
+		assert.Equal(t, 60.0, b.Interest)
+	})
+
+	t.Run("the balloon is principal", func(t *testing.T) {
+		l := structuredLoan(t, ScheduleStructure{Type: ScheduleBalloon, BalloonAmount: 300})
+		schedule, err := l.GenerateSchedule()
+		require.NoError(t, err)
+
+		b := CalculateOutstanding(l, payWeeks(schedule, 9), nil, structureStart.AddDate(0, 0, 64), DefaultPaymentPolicy(), TaxPolicy{})
+
+		assert.Equal(t, 380.0, b.Installments)
+		assert.Equal(t, 370.0, b.Principal)
+		assert.Equal(t, 10.0, b.Interest)
+	})
+}
+
+func TestStructuredLoanCannotBeReshaped(t *testing.T) {
+	l := structuredLoan(t, ScheduleStructure{Type: ScheduleBalloon, BalloonAmount: 300})
+	l.ID = 8
+	schedule, err := l.GenerateSchedule()
+	require.NoError(t, err)
+	today := structureStart.AddDate(0, 0, 1)
+
+	_, err = PlanRepricing(l, schedule, 0.2, today.AddDate(0, 0, 14))
+	assert.ErrorIs(t, err, apperrors.ErrConflict)
+
+	_, err = PlanScheduleAdjustment(l, schedule, ScheduleAdjustment{Kind: AdjustmentPaymentHoliday, StartsOn: today, Weeks: 2, Reason: "harvest"}, today)
+	assert.ErrorIs(t, err, apperrors.ErrConflict)
+
+	_, err = PlanPrepayment(l, schedule, 100, PrepaymentReduceTerm, today)
+	assert.ErrorIs(t, err, apperrors.ErrConflict)
+}
+
+func TestNewProductCatalog(t *testing.T) {
+	c, err := NewProductCatalog(map[string]Product{
+		" Bridge ": {Schedule: "interest_only", InterestOnlyWeeks: 4},
+		"harvest":  {Schedule: "BALLOON", BalloonShare: 0.3},
+		"plain":    {Schedule: "standard"},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Product{
+		"bridge":  {Schedule: ScheduleInterestOnly, InterestOnlyWeeks: 4},
+		"harvest": {Schedule: ScheduleBalloon, BalloonShare: 0.3},
+		"plain":   {Schedule: ScheduleStandard},
+	}, c.Products)
+
+	for name, p := range map[string]Product{
+		"unknown schedule":            {Schedule: "step_up"},
+		"no interest-only weeks":      {Schedule: ScheduleInterestOnly},
+		"balloon of the principal":    {Schedule: ScheduleBalloon, BalloonShare: 1},
+		"balloon without a share":     {Schedule: ScheduleBalloon},
+		"standard with a balloon":     {Schedule: ScheduleStandard, BalloonShare: 0.2},
+		"balloon with deferred weeks": {Schedule: ScheduleBalloon, BalloonShare: 0.2, InterestOnlyWeeks: 2},
+	} {
+		t.Run("rejects a product with "+name, func(t *testing.T) {
+			_, err := NewProductCatalog(map[string]Product{"p": p})
+
+			assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+		})
+	}
+}
+
+func TestProductCatalogStructureFor(t *testing.T) {
+	c := ProductCatalog{Products: map[string]Product{"harvest": {Schedule: ScheduleBalloon, BalloonShare: 0.3}}}
+
+	s, err := c.structureFor("Harvest", 1234.56)
+	require.NoError(t, err)
+	assert.Equal(t, ScheduleStructure{Type: ScheduleBalloon, BalloonAmount: 370.37}, s)
+
+	s, err = c.structureFor("", 1000)
+	require.NoError(t, err)
+	assert.Equal(t, ScheduleStandard, s.Kind())
+
+	_, err = c.structureFor("bridge", 1000)
+	assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+}
+
+func TestCreateLoanWithProduct(t *testing.T) {
+	ctx := context.Background()
+	products := ProductCatalog{Products: map[string]Product{
+		"bridge": {Schedule: ScheduleInterestOnly, InterestOnlyWeeks: 4},
+	}}
+	newService := func() (LoanService, *MockRepository) {
+		mockRepo := new(MockRepository)
+		mockCustomerService := new(MockCustomerService)
+		mockCustomerService.On("GetCustomer", ctx, int64(1)).Return(&customer.Customer{CustomerID: 1, Active: true}, nil)
+		mockCustomerService.On("RequestRiskScore", ctx, mock.Anything, customer.RiskScoreLoanApplication, mock.Anything).Return()
+		return NewLoanService(mockRepo, mockCustomerService, DefaultPaymentPolicy(), DefaultDelinquencyPolicy(), TaxPolicy{}, CreditPolicy{}, products, nil, logger), mockRepo
+	}
+
+	t.Run("books the product's schedule", func(t *testing.T) {
+		service, mockRepo := newService()
+		mockRepo.On("CreateLoan", ctx, int64(1), mock.MatchedBy(func(l *Loan) bool {
+			return l.Structure == ScheduleStructure{Type: ScheduleInterestOnly, InterestOnlyWeeks: 4} && l.WeeklyPaymentAmount == 176.67
+		}), mock.MatchedBy(func(schedule []ScheduleEntry) bool {
+			return len(schedule) == 10 && schedule[3].DueAmount == 10 && schedule[4].DueAmount == 176.67
+		})).Return(&Loan{ID: 9}, nil).Once()
+
+		created, err := service.CreateLoan(ctx, 1, 1000, 10, 0.1, structureStart, "", uuid.Nil, "BRIDGE")
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(9), created.ID)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("refuses an unknown product", func(t *testing.T) {
+		service, mockRepo := newService()
+
+		_, err := service.CreateLoan(ctx, 1, 1000, 10, 0.1, structureStart, "", uuid.Nil, "harvest")
+
+		assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+		mockRepo.AssertNotCalled(t, "CreateLoan", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("refuses a term the interest-only weeks do not fit", func(t *testing.T) {
+		service, _ := newService()
+
+		_, err := service.CreateLoan(ctx, 1, 1000, 4, 0.1, structureStart, "", uuid.Nil, "bridge")
+
+		assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+	})
+}
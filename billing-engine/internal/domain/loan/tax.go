@@ -0,0 +1,160 @@
+package loan
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TaxRates are the rates one jurisdiction charges, as fractions: 0.11 is
+// 11%. Fee types missing from Fees are not taxed.
+type TaxRates struct {
+	Fees     map[FeeType]float64
+	Interest float64
+}
+
+// TaxPolicy decides the tax charged on fees and interest. Loans carry no
+// jurisdiction of their own, so every loan is taxed at the rates of
+// Jurisdiction. The zero policy charges no tax.
+type TaxPolicy struct {
+	Jurisdiction string
+	Rates        map[string]TaxRates
+}
+
+// NewTaxPolicy validates the configured rates. Jurisdictions and fee types
+// are matched without regard to case, because configuration keys are
+// lowercased when they are read.
+func NewTaxPolicy(jurisdiction string, rates map[string]TaxRates) (TaxPolicy, error) {
+	p := TaxPolicy{Jurisdiction: strings.ToUpper(strings.TrimSpace(jurisdiction)), Rates: make(map[string]TaxRates, len(rates))}
+	for name, r := range rates {
+		name = strings.ToUpper(strings.TrimSpace(name))
+		if err := checkTaxRate(r.Interest, name+" interest"); err != nil {
+			return TaxPolicy{}, err
+		}
+		fees := make(map[FeeType]float64, len(r.Fees))
+		for feeType, rate := range r.Fees {
+			feeType = FeeType(strings.ToUpper(string(feeType)))
+			if _, ok := feeType.info(); !ok {
+				return TaxPolicy{}, fmt.Errorf("%w: %s taxes unknown fee type %q", apperrors.ErrInvalidArgument, name, feeType)
+			}
+			if err := checkTaxRate(rate, name+" "+string(feeType)); err != nil {
+				return TaxPolicy{}, err
+			}
+			fees[feeType] = rate
+		}
+		p.Rates[name] = TaxRates{Fees: fees, Interest: r.Interest}
+	}
+	if _, ok := p.Rates[p.Jurisdiction]; p.Jurisdiction != "" && !ok {
+		return TaxPolicy{}, fmt.Errorf("%w: no tax rates for jurisdiction %q", apperrors.ErrInvalidArgument, p.Jurisdiction)
+	}
+	return p, nil
+}
+
+func checkTaxRate(rate float64, what string) error {
+	if math.IsNaN(rate) || rate < 0 || rate >= 1 {
+		return fmt.Errorf("%w: %s tax rate must be at least 0 and below 1, got %v", apperrors.ErrInvalidArgument, what, rate)
+	}
+	return nil
+}
+
+func (p TaxPolicy) rates() TaxRates {
+	if p.Jurisdiction == "" {
+		return TaxRates{}
+	}
+	return p.Rates[p.Jurisdiction]
+}
+
+// feeTax is the tax line of a fee about to be posted, or nil when its type
+// is not taxed or the tax rounds to nothing.
+func (p TaxPolicy) feeTax(fee *Fee, round func(Money) Money) *TaxLine {
+	rate := p.rates().Fees[fee.Type]
+	if rate == 0 {
+		return nil
+	}
+	amount := round(fee.Amount * rate)
+	if amount <= 0 {
+		return nil
+	}
+	return &TaxLine{LoanID: fee.LoanID, Jurisdiction: p.Jurisdiction, Rate: rate, TaxableAmount: fee.Amount, Amount: amount, CreatedAt: fee.PostedAt}
+}
+
+// interestTax is the tax on the interest that has accrued on the unpaid
+// installments, as the outstanding breakdown lists it, and false when there is none.
+func (p TaxPolicy) interestTax(interest Money, round func(Money) Money) (BalanceItem, bool) {
+	rate := p.rates().Interest
+	if rate == 0 || interest <= 0 {
+		return BalanceItem{}, false
+	}
+	amount := round(interest * rate)
+	if amount <= 0 {
+		return BalanceItem{}, false
+	}
+	return BalanceItem{Kind: BalanceTax, Amount: amount, Description: fmt.Sprintf("Tax on interest (%s %s%%)", p.Jurisdiction, formatPercent(rate))}, true
+}
+
+func formatPercent(rate float64) string {
+	return strconv.FormatFloat(roundTo(rate*100, 4), 'f', -1, 64)
+}
+
+// TaxLine is the tax charged on a fee. It is worked out when the fee is
+// posted and keeps that rate when the configured rates change later; it is
+// waived with its fee.
+type TaxLine struct {
+	ID            int64
+	FeeID         int64
+	LoanID        int64
+	Jurisdiction  string
+	Rate          float64
+	TaxableAmount Money
+	Amount        Money
+	CreatedAt     time.Time
+}
+
+// TaxBalanceItem is the tax on the fee as the outstanding breakdown lists
+// it. The fee must have a tax line.
+func (f *Fee) TaxBalanceItem() BalanceItem {
+	return BalanceItem{Kind: BalanceTax, Amount: f.Tax.Amount, Description: "Tax on " + f.BalanceItem().Description}
+}
+
+// TaxTotals totals the tax lines of one jurisdiction and fee type.
+// WaivedAmount is the part of Amount whose fees were waived since.
+type TaxTotals struct {
+	Jurisdiction  string
+	FeeType       FeeType
+	Lines         int
+	TaxableAmount Money
+	Amount        Money
+	WaivedAmount  Money
+}
+
+// TaxReport totals the tax lines created between From and To, inclusive.
+// Tax on interest is not included: it is only worked out on the accrued
+// interest and never stored.
+type TaxReport struct {
+	From         time.Time
+	To           time.Time
+	Rows         []TaxTotals
+	TotalAmount  Money
+	TotalWaived  Money
+	TotalPayable Money
+}
+
+func newTaxReport(from, to time.Time, rows []TaxTotals, round func(Money) Money) *TaxReport {
+	report := &TaxReport{From: from, To: to, Rows: make([]TaxTotals, 0, len(rows))}
+	var total, waived Money
+	for _, row := range rows {
+		row.TaxableAmount = round(row.TaxableAmount)
+		row.Amount = round(row.Amount)
+		row.WaivedAmount = round(row.WaivedAmount)
+		report.Rows = append(report.Rows, row)
+		total += row.Amount
+		waived += row.WaivedAmount
+	}
+	report.TotalAmount = round(total)
+	report.TotalWaived = round(waived)
+	report.TotalPayable = round(total - waived)
+	return report
+}
@@ -0,0 +1,55 @@
+package loan
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTaxPolicy(t *testing.T) {
+	t.Run("matches configuration keys without regard to case", func(t *testing.T) {
+		p, err := NewTaxPolicy(" id ", map[string]TaxRates{"id": {Fees: map[FeeType]float64{"processing": 0.11}, Interest: 0.1}})
+
+		require.NoError(t, err)
+		assert.Equal(t, "ID", p.Jurisdiction)
+		assert.Equal(t, 0.11, p.rates().Fees[FeeProcessing])
+		assert.Equal(t, 0.1, p.rates().Interest)
+	})
+
+	t.Run("charges nothing without a jurisdiction", func(t *testing.T) {
+		p, err := NewTaxPolicy("", map[string]TaxRates{"ID": {Interest: 0.1}})
+
+		require.NoError(t, err)
+		assert.Zero(t, p.rates().Interest)
+	})
+
+	for name, rates := range map[string]map[string]TaxRates{
+		"unknown jurisdiction": {"SG": {Interest: 0.09}},
+		"unknown fee type":     {"ID": {Fees: map[FeeType]float64{"PENALTY": 0.11}}},
+		"negative rate":        {"ID": {Interest: -0.1}},
+		"rate of 100%":         {"ID": {Fees: map[FeeType]float64{FeeLegal: 1}}},
+	} {
+		t.Run("rejects "+name, func(t *testing.T) {
+			_, err := NewTaxPolicy("ID", rates)
+
+			assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+		})
+	}
+}
+
+func TestTaxPolicyFeeTax(t *testing.T) {
+	p, err := NewTaxPolicy("ID", map[string]TaxRates{"ID": {Fees: map[FeeType]float64{FeeBounce: 0.11}}})
+	require.NoError(t, err)
+	postedAt := time.Date(2025, 1, 14, 9, 0, 0, 0, time.UTC)
+	round := DefaultPaymentPolicy().Round
+
+	tax := p.feeTax(&Fee{LoanID: 3, Type: FeeBounce, Amount: 25.13, PostedAt: postedAt}, round)
+
+	assert.Equal(t, &TaxLine{LoanID: 3, Jurisdiction: "ID", Rate: 0.11, TaxableAmount: 25.13, Amount: 2.76, CreatedAt: postedAt}, tax)
+	assert.Nil(t, p.feeTax(&Fee{Type: FeeLegal, Amount: 50}, round), "untaxed fee type")
+	assert.Nil(t, p.feeTax(&Fee{Type: FeeBounce, Amount: 0.01}, round), "tax rounds to nothing")
+	assert.Nil(t, TaxPolicy{}.feeTax(&Fee{Type: FeeBounce, Amount: 25}, round))
+}
@@ -0,0 +1,85 @@
+package note
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type SubjectType string
+
+const (
+	SubjectLoan     SubjectType = "loan"
+	SubjectCustomer SubjectType = "customer"
+
+	MaxBodyLength     = 4000
+	MaxFileNameLength = 255
+
+	defaultContentType = "application/octet-stream"
+)
+
+// Subject is the loan or customer a note or attachment belongs to.
+type Subject struct {
+	Type SubjectType
+	ID   int64
+}
+
+func (s Subject) Validate() error {
+	if s.Type != SubjectLoan && s.Type != SubjectCustomer {
+		return fmt.Errorf("unknown subject type %q", s.Type)
+	}
+	if s.ID <= 0 {
+		return fmt.Errorf("%s ID must be a positive number", s.Type)
+	}
+	return nil
+}
+
+func (s Subject) String() string {
+	return fmt.Sprintf("%s %d", s.Type, s.ID)
+}
+
+type Note struct {
+	ID        int64
+	Subject   Subject
+	Body      string
+	Author    string
+	CreatedAt time.Time
+}
+
+// Attachment holds the metadata of a file whose content lives in object
+// storage under StorageKey.
+type Attachment struct {
+	ID          int64
+	Subject     Subject
+	FileName    string
+	ContentType string
+	SizeBytes   int64
+	StorageKey  string
+	UploadedBy  string
+	CreatedAt   time.Time
+}
+
+func validateBody(body string) error {
+	switch {
+	case body == "":
+		return fmt.Errorf("body cannot be empty")
+	case utf8.RuneCountInString(body) > MaxBodyLength:
+		return fmt.Errorf("body cannot exceed %d characters", MaxBodyLength)
+	}
+	return nil
+}
+
+// cleanFileName drops any directory part a client may send along with the
+// file name.
+func cleanFileName(name string) (string, error) {
+	name = strings.TrimSpace(path.Base(strings.ReplaceAll(name, "\\", "/")))
+	switch {
+	case name == "" || name == "." || name == "/":
+		return "", fmt.Errorf("file name cannot be empty")
+	case utf8.RuneCountInString(name) > MaxFileNameLength:
+		return "", fmt.Errorf("file name cannot exceed %d characters", MaxFileNameLength)
+	}
+	return name, nil
+}
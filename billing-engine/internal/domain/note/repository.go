@@ -0,0 +1,33 @@
+package note
+
+import (
+	"context"
+	"io"
+)
+
+type Repository interface {
+	CreateNote(ctx context.Context, note *Note) error
+
+	ListNotes(ctx context.Context, subject Subject) ([]Note, error)
+
+	// DeleteNote removes the note only when it belongs to subject.
+	DeleteNote(ctx context.Context, subject Subject, noteID int64) error
+
+	CreateAttachment(ctx context.Context, attachment *Attachment) error
+
+	ListAttachments(ctx context.Context, subject Subject) ([]Attachment, error)
+
+	GetAttachment(ctx context.Context, subject Subject, attachmentID int64) (*Attachment, error)
+
+	DeleteAttachment(ctx context.Context, subject Subject, attachmentID int64) error
+
+	SubjectExists(ctx context.Context, subject Subject) (bool, error)
+}
+
+// ObjectStore keeps attachment content. Keys are chosen by the service and
+// are unique per upload.
+type ObjectStore interface {
+	Put(ctx context.Context, key, contentType string, body io.Reader, size int64) error
+
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,63 @@
+package note
+
+import (
+	"context"
+	"io"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) CreateNote(ctx context.Context, note *Note) error {
+	return m.Called(ctx, note).Error(0)
+}
+
+func (m *MockRepository) ListNotes(ctx context.Context, subject Subject) ([]Note, error) {
+	args := m.Called(ctx, subject)
+	notes, _ := args.Get(0).([]Note)
+	return notes, args.Error(1)
+}
+
+func (m *MockRepository) DeleteNote(ctx context.Context, subject Subject, noteID int64) error {
+	return m.Called(ctx, subject, noteID).Error(0)
+}
+
+func (m *MockRepository) CreateAttachment(ctx context.Context, attachment *Attachment) error {
+	return m.Called(ctx, attachment).Error(0)
+}
+
+func (m *MockRepository) ListAttachments(ctx context.Context, subject Subject) ([]Attachment, error) {
+	args := m.Called(ctx, subject)
+	attachments, _ := args.Get(0).([]Attachment)
+	return attachments, args.Error(1)
+}
+
+func (m *MockRepository) GetAttachment(ctx context.Context, subject Subject, attachmentID int64) (*Attachment, error) {
+	args := m.Called(ctx, subject, attachmentID)
+	attachment, _ := args.Get(0).(*Attachment)
+	return attachment, args.Error(1)
+}
+
+func (m *MockRepository) DeleteAttachment(ctx context.Context, subject Subject, attachmentID int64) error {
+	return m.Called(ctx, subject, attachmentID).Error(0)
+}
+
+func (m *MockRepository) SubjectExists(ctx context.Context, subject Subject) (bool, error) {
+	args := m.Called(ctx, subject)
+	return args.Bool(0), args.Error(1)
+}
+
+type MockObjectStore struct {
+	mock.Mock
+}
+
+func (m *MockObjectStore) Put(ctx context.Context, key, contentType string, body io.Reader, size int64) error {
+	return m.Called(ctx, key, contentType, body, size).Error(0)
+}
+
+func (m *MockObjectStore) Delete(ctx context.Context, key string) error {
+	return m.Called(ctx, key).Error(0)
+}
@@ -0,0 +1,211 @@
+package note
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+type Service interface {
+	AddNote(ctx context.Context, subject Subject, body, author string) (*Note, error)
+	ListNotes(ctx context.Context, subject Subject) ([]Note, error)
+	DeleteNote(ctx context.Context, subject Subject, noteID int64) error
+	AddAttachment(ctx context.Context, subject Subject, upload Upload) (*Attachment, error)
+	ListAttachments(ctx context.Context, subject Subject) ([]Attachment, error)
+	DeleteAttachment(ctx context.Context, subject Subject, attachmentID int64) error
+}
+
+// Upload is a file received from a client. Size is the exact length of
+// Content.
+type Upload struct {
+	FileName    string
+	ContentType string
+	Size        int64
+	Content     io.Reader
+	UploadedBy  string
+}
+
+var _ Service = (*service)(nil)
+
+type service struct {
+	repo   Repository
+	store  ObjectStore
+	logger *slog.Logger
+}
+
+// NewService wires the note service. store may be nil when no object storage
+// is configured; notes keep working and attachment uploads are refused.
+func NewService(repo Repository, store ObjectStore, logger *slog.Logger) Service {
+	if repo == nil {
+		panic("note repository cannot be nil")
+	}
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+		logger.Warn("Warning: No logger provided to NewService, using default stderr handler")
+	}
+	return &service{
+		repo:   repo,
+		store:  store,
+		logger: logger.With(slog.String("component", "noteService")),
+	}
+}
+
+func (s *service) AddNote(ctx context.Context, subject Subject, body, author string) (*Note, error) {
+	if err := subject.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err)
+	}
+	body = strings.TrimSpace(body)
+	if err := validateBody(body); err != nil {
+		return nil, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err)
+	}
+
+	n := &Note{Subject: subject, Body: body, Author: strings.TrimSpace(author)}
+	if err := s.repo.CreateNote(ctx, n); err != nil {
+		s.logger.ErrorContext(ctx, "Failed to create note", slog.String("subject", subject.String()), slog.Any("error", err))
+		return nil, fmt.Errorf("failed to create note for %s: %w", subject, err)
+	}
+
+	s.logger.InfoContext(ctx, "Note created", slog.String("subject", subject.String()), slog.Int64("noteID", n.ID))
+	return n, nil
+}
+
+func (s *service) ListNotes(ctx context.Context, subject Subject) ([]Note, error) {
+	if err := subject.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err)
+	}
+	notes, err := s.repo.ListNotes(ctx, subject)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notes for %s: %w", subject, err)
+	}
+	if len(notes) == 0 {
+		if err := s.requireSubject(ctx, subject); err != nil {
+			return nil, err
+		}
+	}
+	return notes, nil
+}
+
+func (s *service) DeleteNote(ctx context.Context, subject Subject, noteID int64) error {
+	if err := subject.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err)
+	}
+	if noteID <= 0 {
+		return fmt.Errorf("%w: note ID must be a positive number", apperrors.ErrInvalidArgument)
+	}
+	if err := s.repo.DeleteNote(ctx, subject, noteID); err != nil {
+		return fmt.Errorf("failed to delete note %d of %s: %w", noteID, subject, err)
+	}
+	s.logger.InfoContext(ctx, "Note deleted", slog.String("subject", subject.String()), slog.Int64("noteID", noteID))
+	return nil
+}
+
+// AddAttachment stores the content first and records the metadata second. If
+// the metadata cannot be saved the stored object is removed again.
+func (s *service) AddAttachment(ctx context.Context, subject Subject, upload Upload) (*Attachment, error) {
+	if s.store == nil {
+		return nil, fmt.Errorf("%w: attachment storage is not configured", apperrors.ErrUnavailable)
+	}
+	if err := subject.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err)
+	}
+	fileName, err := cleanFileName(upload.FileName)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err)
+	}
+	if upload.Size <= 0 || upload.Content == nil {
+		return nil, fmt.Errorf("%w: file cannot be empty", apperrors.ErrInvalidArgument)
+	}
+	contentType := strings.TrimSpace(upload.ContentType)
+	if contentType == "" {
+		contentType = defaultContentType
+	}
+
+	a := &Attachment{
+		Subject:     subject,
+		FileName:    fileName,
+		ContentType: contentType,
+		SizeBytes:   upload.Size,
+		StorageKey:  fmt.Sprintf("%ss/%d/%s", subject.Type, subject.ID, uuid.New()),
+		UploadedBy:  strings.TrimSpace(upload.UploadedBy),
+	}
+
+	if err := s.store.Put(ctx, a.StorageKey, a.ContentType, upload.Content, a.SizeBytes); err != nil {
+		s.logger.ErrorContext(ctx, "Failed to store attachment content", slog.String("key", a.StorageKey), slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to store attachment: %v", apperrors.ErrInternalServer, err)
+	}
+
+	if err := s.repo.CreateAttachment(ctx, a); err != nil {
+		s.logger.ErrorContext(ctx, "Failed to record attachment, removing stored object", slog.String("key", a.StorageKey), slog.Any("error", err))
+		if delErr := s.store.Delete(context.WithoutCancel(ctx), a.StorageKey); delErr != nil {
+			s.logger.ErrorContext(ctx, "Failed to remove orphaned attachment object", slog.String("key", a.StorageKey), slog.Any("error", delErr))
+		}
+		return nil, fmt.Errorf("failed to record attachment for %s: %w", subject, err)
+	}
+
+	s.logger.InfoContext(ctx, "Attachment stored", slog.String("subject", subject.String()), slog.Int64("attachmentID", a.ID))
+	return a, nil
+}
+
+func (s *service) ListAttachments(ctx context.Context, subject Subject) ([]Attachment, error) {
+	if err := subject.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err)
+	}
+	attachments, err := s.repo.ListAttachments(ctx, subject)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list attachments for %s: %w", subject, err)
+	}
+	if len(attachments) == 0 {
+		if err := s.requireSubject(ctx, subject); err != nil {
+			return nil, err
+		}
+	}
+	return attachments, nil
+}
+
+// DeleteAttachment removes the metadata before the object. A failed object
+// delete leaves an unreferenced object behind, which is logged but not
+// reported to the caller.
+func (s *service) DeleteAttachment(ctx context.Context, subject Subject, attachmentID int64) error {
+	if s.store == nil {
+		return fmt.Errorf("%w: attachment storage is not configured", apperrors.ErrUnavailable)
+	}
+	if err := subject.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", apperrors.ErrInvalidArgument, err)
+	}
+	if attachmentID <= 0 {
+		return fmt.Errorf("%w: attachment ID must be a positive number", apperrors.ErrInvalidArgument)
+	}
+
+	a, err := s.repo.GetAttachment(ctx, subject, attachmentID)
+	if err != nil {
+		return fmt.Errorf("failed to get attachment %d of %s: %w", attachmentID, subject, err)
+	}
+	if err := s.repo.DeleteAttachment(ctx, subject, attachmentID); err != nil {
+		return fmt.Errorf("failed to delete attachment %d of %s: %w", attachmentID, subject, err)
+	}
+	if err := s.store.Delete(ctx, a.StorageKey); err != nil {
+		s.logger.WarnContext(ctx, "Attachment deleted but its object could not be removed", slog.String("key", a.StorageKey), slog.Any("error", err))
+	}
+
+	s.logger.InfoContext(ctx, "Attachment deleted", slog.String("subject", subject.String()), slog.Int64("attachmentID", attachmentID))
+	return nil
+}
+
+// requireSubject turns an empty listing for an unknown loan or customer into
+// a not found error.
+func (s *service) requireSubject(ctx context.Context, subject Subject) error {
+	exists, err := s.repo.SubjectExists(ctx, subject)
+	if err != nil {
+		return fmt.Errorf("failed to check %s: %w", subject, err)
+	}
+	if !exists {
+		return fmt.Errorf("%w: %s", apperrors.ErrNotFound, subject)
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package note
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+var loanSubject = Subject{Type: SubjectLoan, ID: 42}
+
+func TestServiceAddNote(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("trims and stores the note", func(t *testing.T) {
+		repo := new(MockRepository)
+		svc := NewService(repo, nil, testLogger)
+		repo.On("CreateNote", ctx, mock.MatchedBy(func(n *Note) bool {
+			n.ID = 5
+			return n.Subject == loanSubject && n.Body == "Called customer" && n.Author == "ops"
+		})).Return(nil).Once()
+
+		n, err := svc.AddNote(ctx, loanSubject, "  Called customer ", "ops")
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), n.ID)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("rejects an empty body", func(t *testing.T) {
+		repo := new(MockRepository)
+		svc := NewService(repo, nil, testLogger)
+
+		_, err := svc.AddNote(ctx, loanSubject, "   ", "ops")
+
+		assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+		repo.AssertNotCalled(t, "CreateNote", mock.Anything, mock.Anything)
+	})
+
+	t.Run("rejects an unknown subject type", func(t *testing.T) {
+		svc := NewService(new(MockRepository), nil, testLogger)
+
+		_, err := svc.AddNote(ctx, Subject{Type: "invoice", ID: 1}, "text", "ops")
+
+		assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+	})
+}
+
+func TestServiceListNotes(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("returns the notes", func(t *testing.T) {
+		repo := new(MockRepository)
+		svc := NewService(repo, nil, testLogger)
+		repo.On("ListNotes", ctx, loanSubject).Return([]Note{{ID: 1, Subject: loanSubject, Body: "a"}}, nil).Once()
+
+		notes, err := svc.ListNotes(ctx, loanSubject)
+
+		require.NoError(t, err)
+		assert.Len(t, notes, 1)
+		repo.AssertNotCalled(t, "SubjectExists", mock.Anything, mock.Anything)
+	})
+
+	t.Run("unknown subject is not found", func(t *testing.T) {
+		repo := new(MockRepository)
+		svc := NewService(repo, nil, testLogger)
+		repo.On("ListNotes", ctx, loanSubject).Return([]Note{}, nil).Once()
+		repo.On("SubjectExists", ctx, loanSubject).Return(false, nil).Once()
+
+		_, err := svc.ListNotes(ctx, loanSubject)
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+}
+
+func TestServiceAddAttachment(t *testing.T) {
+	ctx := context.Background()
+	upload := Upload{FileName: `C:\scans\contract.pdf`, ContentType: "application/pdf", Size: 4, Content: strings.NewReader("%PDF"), UploadedBy: "ops"}
+
+	t.Run("stores content then metadata", func(t *testing.T) {
+		repo, store := new(MockRepository), new(MockObjectStore)
+		svc := NewService(repo, store, testLogger)
+		var key string
+		store.On("Put", ctx, mock.MatchedBy(func(k string) bool {
+			key = k
+			return strings.HasPrefix(k, "loans/42/")
+		}), "application/pdf", upload.Content, int64(4)).Return(nil).Once()
+		repo.On("CreateAttachment", ctx, mock.MatchedBy(func(a *Attachment) bool {
+			return a.FileName == "contract.pdf" && a.StorageKey == key && a.SizeBytes == 4
+		})).Return(nil).Once()
+
+		a, err := svc.AddAttachment(ctx, loanSubject, upload)
+
+		require.NoError(t, err)
+		assert.Equal(t, "contract.pdf", a.FileName)
+		repo.AssertExpectations(t)
+		store.AssertExpectations(t)
+	})
+
+	t.Run("removes the object when metadata cannot be saved", func(t *testing.T) {
+		repo, store := new(MockRepository), new(MockObjectStore)
+		svc := NewService(repo, store, testLogger)
+		store.On("Put", ctx, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+		repo.On("CreateAttachment", ctx, mock.Anything).Return(apperrors.ErrNotFound).Once()
+		store.On("Delete", mock.Anything, mock.Anything).Return(nil).Once()
+
+		_, err := svc.AddAttachment(ctx, loanSubject, upload)
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+		store.AssertExpectations(t)
+	})
+
+	t.Run("refuses uploads without object storage", func(t *testing.T) {
+		svc := NewService(new(MockRepository), nil, testLogger)
+
+		_, err := svc.AddAttachment(ctx, loanSubject, upload)
+
+		assert.ErrorIs(t, err, apperrors.ErrUnavailable)
+	})
+}
+
+func TestServiceDeleteAttachment(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("deletes metadata and object", func(t *testing.T) {
+		repo, store := new(MockRepository), new(MockObjectStore)
+		svc := NewService(repo, store, testLogger)
+		repo.On("GetAttachment", ctx, loanSubject, int64(3)).Return(&Attachment{ID: 3, StorageKey: "loans/42/k"}, nil).Once()
+		repo.On("DeleteAttachment", ctx, loanSubject, int64(3)).Return(nil).Once()
+		store.On("Delete", ctx, "loans/42/k").Return(errors.New("timeout")).Once()
+
+		err := svc.DeleteAttachment(ctx, loanSubject, 3)
+
+		assert.NoError(t, err, "object cleanup failures are logged, not returned")
+		repo.AssertExpectations(t)
+		store.AssertExpectations(t)
+	})
+
+	t.Run("attachment of another subject is not found", func(t *testing.T) {
+		repo, store := new(MockRepository), new(MockObjectStore)
+		svc := NewService(repo, store, testLogger)
+		repo.On("GetAttachment", ctx, loanSubject, int64(3)).Return(nil, apperrors.ErrNotFound).Once()
+
+		err := svc.DeleteAttachment(ctx, loanSubject, 3)
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+		store.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+	})
+}
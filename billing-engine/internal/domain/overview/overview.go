@@ -0,0 +1,66 @@
+package overview
+
+import (
+	"billing-engine/internal/domain/collections"
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/domain/loan"
+	"context"
+	"time"
+)
+
+const (
+	// RecentPayments and RecentNotifications are how many of each an
+	// overview shows, newest first.
+	RecentPayments      = 10
+	RecentNotifications = 10
+)
+
+// CustomerOverview is what support looks at when a customer gets in touch:
+// the customer, their loan, what they paid lately, what they were told and
+// what collections is doing about it.
+type CustomerOverview struct {
+	Customer *customer.Customer
+	// Loans holds the customer's loans that are not paid off. A customer has
+	// one loan at a time, so there is at most one.
+	Loans []LoanOverview
+	// Payments are the latest payments on the customer's loan, kept after it
+	// is paid off.
+	Payments []loan.Payment
+	// Collections is the loan's open collections assignment, nil while there
+	// is none.
+	Collections *OpenCollections
+	// Notifications is empty, rather than missing, when NotificationsAvailable
+	// is false: notify-service is not configured or could not be reached.
+	Notifications          []Notification
+	NotificationsAvailable bool
+}
+
+// LoanOverview is a loan with what is owed on it today.
+type LoanOverview struct {
+	Loan        *loan.Loan
+	Outstanding loan.Money
+}
+
+// OpenCollections is an open assignment with its actions, newest first.
+type OpenCollections struct {
+	Assignment collections.Assignment
+	Actions    []collections.Action
+}
+
+// Notification is an entry of notify-service's delivery log.
+type Notification struct {
+	ID        int64
+	Event     string
+	Channel   string
+	Recipient string
+	Subject   string
+	Status    string
+	Error     string
+	SentAt    *time.Time
+	CreatedAt time.Time
+}
+
+// NotificationSource reads what was sent to a customer, newest first.
+type NotificationSource interface {
+	ListNotifications(ctx context.Context, customerID int64, limit int) ([]Notification, error)
+}
@@ -0,0 +1,113 @@
+package overview
+
+import (
+	"billing-engine/internal/domain/collections"
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/domain/loan"
+	"context"
+	"log/slog"
+	"os"
+)
+
+type Service interface {
+	// GetOverview gathers the customer's overview from the services that own
+	// each part. Only the notifications may be left out; any other part that
+	// cannot be read fails the call.
+	GetOverview(ctx context.Context, customerID int64) (*CustomerOverview, error)
+}
+
+var _ Service = (*service)(nil)
+
+type service struct {
+	customers     customer.CustomerService
+	loans         loan.LoanService
+	collections   collections.Service
+	notifications NotificationSource
+	logger        *slog.Logger
+}
+
+// NewService builds the overview service. notifications may be nil, in which
+// case overviews carry no notifications.
+func NewService(customers customer.CustomerService, loans loan.LoanService, collections collections.Service, notifications NotificationSource, logger *slog.Logger) Service {
+	if customers == nil {
+		panic("customer service cannot be nil")
+	}
+	if loans == nil {
+		panic("loan service cannot be nil")
+	}
+	if collections == nil {
+		panic("collections service cannot be nil")
+	}
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+		logger.Warn("Warning: No logger provided to overview.NewService, using default stderr handler")
+	}
+	return &service{
+		customers:     customers,
+		loans:         loans,
+		collections:   collections,
+		notifications: notifications,
+		logger:        logger.With(slog.String("component", "overviewService")),
+	}
+}
+
+func (s *service) GetOverview(ctx context.Context, customerID int64) (*CustomerOverview, error) {
+	cust, err := s.customers.GetCustomer(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+	o := &CustomerOverview{Customer: cust, Loans: []LoanOverview{}, Payments: []loan.Payment{}, Notifications: []Notification{}}
+
+	if cust.LoanID != nil {
+		if err := s.addLoan(ctx, o, *cust.LoanID); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.notifications != nil {
+		notifications, err := s.notifications.ListNotifications(ctx, customerID, RecentNotifications)
+		if err != nil {
+			s.logger.WarnContext(ctx, "Notifications left out of the customer overview", slog.Int64("customerID", customerID), slog.Any("error", err))
+		} else {
+			o.Notifications, o.NotificationsAvailable = notifications, true
+		}
+	}
+	return o, nil
+}
+
+// addLoan fills in the loan, its payments and its open assignment. The
+// assignment is given the loan's current figures, which it does not carry
+// itself.
+func (s *service) addLoan(ctx context.Context, o *CustomerOverview, loanID int64) error {
+	l, err := s.loans.GetLoan(ctx, loanID, false)
+	if err != nil {
+		return err
+	}
+
+	var outstanding loan.Money
+	if l.Status != loan.StatusPaidOff {
+		if outstanding, err = s.loans.GetOutstanding(ctx, loanID); err != nil {
+			return err
+		}
+		o.Loans = append(o.Loans, LoanOverview{Loan: l, Outstanding: outstanding})
+	}
+
+	if o.Payments, err = s.loans.ListPayments(ctx, loanID, RecentPayments); err != nil {
+		return err
+	}
+
+	assignment, err := s.collections.GetOpenAssignment(ctx, loanID)
+	if err != nil || assignment == nil {
+		return err
+	}
+	actions, err := s.collections.ListActions(ctx, assignment.ID)
+	if err != nil {
+		return err
+	}
+	assignment.CustomerName = o.Customer.Name
+	assignment.LoanStatus = l.Status
+	assignment.DaysPastDue = l.DaysPastDue
+	assignment.Outstanding = outstanding
+	o.Collections = &OpenCollections{Assignment: *assignment, Actions: actions}
+	return nil
+}
@@ -0,0 +1,178 @@
+package overview
+
+import (
+	"billing-engine/internal/domain/collections"
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+type fakeCustomerService struct {
+	customer.CustomerService
+	customers map[int64]*customer.Customer
+}
+
+func (f *fakeCustomerService) GetCustomer(_ context.Context, customerID int64) (*customer.Customer, error) {
+	c, ok := f.customers[customerID]
+	if !ok {
+		return nil, fmt.Errorf("%w: customer %d", apperrors.ErrNotFound, customerID)
+	}
+	return c, nil
+}
+
+type fakeLoanService struct {
+	loan.LoanService
+	loans    map[int64]*loan.Loan
+	payments []loan.Payment
+	limit    int
+}
+
+func (f *fakeLoanService) GetLoan(_ context.Context, loanID int64, includeSchedule bool) (*loan.Loan, error) {
+	l, ok := f.loans[loanID]
+	if !ok {
+		return nil, fmt.Errorf("%w: loan with ID %d not found", apperrors.ErrNotFound, loanID)
+	}
+	if !includeSchedule {
+		withoutSchedule := *l
+		withoutSchedule.Schedule = nil
+		return &withoutSchedule, nil
+	}
+	return l, nil
+}
+
+func (f *fakeLoanService) GetOutstanding(context.Context, int64) (loan.Money, error) {
+	return 440, nil
+}
+
+func (f *fakeLoanService) ListPayments(_ context.Context, _ int64, limit int) ([]loan.Payment, error) {
+	f.limit = limit
+	return f.payments, nil
+}
+
+type fakeCollectionsService struct {
+	collections.Service
+	assignment *collections.Assignment
+	actions    []collections.Action
+}
+
+func (f *fakeCollectionsService) GetOpenAssignment(context.Context, int64) (*collections.Assignment, error) {
+	return f.assignment, nil
+}
+
+func (f *fakeCollectionsService) ListActions(context.Context, int64) ([]collections.Action, error) {
+	return f.actions, nil
+}
+
+type fakeNotifications struct {
+	notifications []Notification
+	err           error
+}
+
+func (f *fakeNotifications) ListNotifications(context.Context, int64, int) ([]Notification, error) {
+	return f.notifications, f.err
+}
+
+func fixture(status loan.LoanStatus) (*fakeCustomerService, *fakeLoanService, *fakeCollectionsService) {
+	loanID := int64(10)
+	paidAt := time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC)
+	customers := &fakeCustomerService{customers: map[int64]*customer.Customer{
+		7: {CustomerID: 7, Name: "Jane Doe", LoanID: &loanID},
+		8: {CustomerID: 8, Name: "John Doe"},
+	}}
+	loans := &fakeLoanService{
+		loans:    map[int64]*loan.Loan{10: {ID: 10, Status: status, DaysPastDue: 9, Schedule: []loan.ScheduleEntry{{}, {}}}},
+		payments: []loan.Payment{{ID: 3, LoanID: 10, Amount: 110, Channel: loan.ChannelCash, PaidAt: paidAt}},
+	}
+	return customers, loans, &fakeCollectionsService{}
+}
+
+func TestServiceGetOverview(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("gathers every part", func(t *testing.T) {
+		customers, loans, colls := fixture(loan.StatusDelinquent)
+		colls.assignment = &collections.Assignment{ID: 4, LoanID: 10, Collector: "alice", Status: collections.AssignmentOpen}
+		colls.actions = []collections.Action{{ID: 5, AssignmentID: 4, Type: collections.ActionCall}}
+		notifications := &fakeNotifications{notifications: []Notification{{ID: 1, Event: "payment_reminder", Status: "SENT"}}}
+
+		o, err := NewService(customers, loans, colls, notifications, testLogger).GetOverview(ctx, 7)
+
+		require.NoError(t, err)
+		assert.Equal(t, "Jane Doe", o.Customer.Name)
+		require.Len(t, o.Loans, 1)
+		assert.Equal(t, loan.Money(440), o.Loans[0].Outstanding)
+		assert.Nil(t, o.Loans[0].Loan.Schedule, "the overview does not carry the schedule")
+		assert.Len(t, o.Payments, 1)
+		assert.Equal(t, RecentPayments, loans.limit)
+		require.NotNil(t, o.Collections)
+		assert.Equal(t, "alice", o.Collections.Assignment.Collector)
+		assert.Equal(t, 9, o.Collections.Assignment.DaysPastDue)
+		assert.Equal(t, loan.Money(440), o.Collections.Assignment.Outstanding)
+		assert.Len(t, o.Collections.Actions, 1)
+		assert.True(t, o.NotificationsAvailable)
+		assert.Len(t, o.Notifications, 1)
+	})
+
+	t.Run("paid-off loans keep their payments", func(t *testing.T) {
+		customers, loans, colls := fixture(loan.StatusPaidOff)
+
+		o, err := NewService(customers, loans, colls, nil, testLogger).GetOverview(ctx, 7)
+
+		require.NoError(t, err)
+		assert.Empty(t, o.Loans)
+		assert.Len(t, o.Payments, 1)
+		assert.Nil(t, o.Collections)
+	})
+
+	t.Run("customer without a loan", func(t *testing.T) {
+		customers, loans, colls := fixture(loan.StatusActive)
+
+		o, err := NewService(customers, loans, colls, nil, testLogger).GetOverview(ctx, 8)
+
+		require.NoError(t, err)
+		assert.Empty(t, o.Loans)
+		assert.Empty(t, o.Payments)
+		assert.False(t, o.NotificationsAvailable, "notify-service is not configured")
+	})
+
+	t.Run("notifications that cannot be read are left out", func(t *testing.T) {
+		customers, loans, colls := fixture(loan.StatusActive)
+		notifications := &fakeNotifications{err: errors.New("connection refused")}
+
+		o, err := NewService(customers, loans, colls, notifications, testLogger).GetOverview(ctx, 7)
+
+		require.NoError(t, err)
+		assert.False(t, o.NotificationsAvailable)
+		assert.NotNil(t, o.Notifications)
+		assert.Len(t, o.Loans, 1)
+	})
+
+	t.Run("unknown customer", func(t *testing.T) {
+		customers, loans, colls := fixture(loan.StatusActive)
+
+		_, err := NewService(customers, loans, colls, nil, testLogger).GetOverview(ctx, 9)
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+
+	t.Run("a loan that cannot be read fails the overview", func(t *testing.T) {
+		customers, loans, colls := fixture(loan.StatusActive)
+		delete(loans.loans, 10)
+
+		_, err := NewService(customers, loans, colls, nil, testLogger).GetOverview(ctx, 7)
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+}
@@ -0,0 +1,122 @@
+package summary
+
+import (
+	"billing-engine/internal/event"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+)
+
+// ProjectedEventTypes are the hub events that change a summary.
+var ProjectedEventTypes = []string{
+	event.TypeCustomerCreated,
+	event.TypeCustomerUpdated,
+	event.TypeCustomerDelinquencyChanged,
+	event.TypeLoanCreated,
+	event.TypeLoanPaymentReceived,
+}
+
+// Projector keeps the summaries current from the events on the hub. The hub
+// disconnects subscribers that fall behind, so every time the projector
+// subscribes it first rebuilds all rows to cover the events it missed.
+// Changes that raise no event, such as the days past due written by the
+// nightly job, are picked up by the next rebuild.
+type Projector struct {
+	service Service
+	hub     *event.Hub
+	logger  *slog.Logger
+	wg      sync.WaitGroup
+	cancel  context.CancelFunc
+}
+
+func NewProjector(s Service, hub *event.Hub, logger *slog.Logger) *Projector {
+	if s == nil || hub == nil || logger == nil {
+		panic("Projector dependencies cannot be nil")
+	}
+	return &Projector{service: s, hub: hub, logger: logger.With(slog.String("component", "summaryProjector"))}
+}
+
+func (p *Projector) Start(ctx context.Context) {
+	loopCtx, cancel := context.WithCancel(ctx)
+	p.cancel = cancel
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		for loopCtx.Err() == nil {
+			p.consume(loopCtx)
+		}
+	}()
+}
+
+// Stop waits for the event being applied to finish.
+func (p *Projector) Stop() {
+	if p.cancel == nil {
+		return
+	}
+	p.cancel()
+	p.wg.Wait()
+}
+
+// consume applies the events of one subscription until the hub drops it or
+// ctx ends.
+func (p *Projector) consume(ctx context.Context) {
+	sub := p.hub.Subscribe(ProjectedEventTypes, 0)
+	defer sub.Close()
+
+	if _, err := p.service.Rebuild(ctx); err != nil && ctx.Err() == nil {
+		p.logger.ErrorContext(ctx, "Failed to rebuild customer loan summaries", slog.Any("error", err))
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case env, ok := <-sub.C:
+			if !ok {
+				p.logger.WarnContext(ctx, "Event hub dropped the summary projector, resubscribing")
+				return
+			}
+			p.apply(ctx, env)
+		}
+	}
+}
+
+// eventSubject reads who an event is about. Customer created and updated
+// events nest the customer in a payload; the loan payment event names only
+// the loan.
+type eventSubject struct {
+	CustomerID int64 `json:"customerId"`
+	LoanID     int64 `json:"loanId"`
+	Payload    *struct {
+		CustomerID int64 `json:"customerId"`
+	} `json:"payload"`
+}
+
+func (p *Projector) apply(ctx context.Context, env event.Envelope) {
+	logCtx := p.logger.With(slog.String("type", env.Type), slog.Int64("eventID", env.ID))
+	var subject eventSubject
+	if err := json.Unmarshal(env.Payload, &subject); err != nil {
+		logCtx.WarnContext(ctx, "Skipping undecodable event", slog.Any("error", err))
+		return
+	}
+	customerID := subject.CustomerID
+	if subject.Payload != nil {
+		customerID = subject.Payload.CustomerID
+	}
+
+	var err error
+	switch {
+	case customerID != 0:
+		err = p.service.Refresh(ctx, customerID)
+	case subject.LoanID != 0:
+		err = p.service.RefreshForLoan(ctx, subject.LoanID)
+	default:
+		return
+	}
+	if err != nil {
+		// The row catches up on the next event for the customer or the next
+		// rebuild.
+		logCtx.WarnContext(ctx, "Failed to refresh customer loan summary", slog.Any("error", err))
+	}
+}
@@ -0,0 +1,42 @@
+package summary
+
+import (
+	"billing-engine/internal/event"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+func waitFor(t *testing.T, done <-chan struct{}, what string) {
+	t.Helper()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for %s", what)
+	}
+}
+
+func TestProjectorAppliesHubEvents(t *testing.T) {
+	hub := event.NewHub(8, 0, testLogger)
+	repo := new(MockRepository)
+	rebuilt := make(chan struct{})
+	refreshed := make(chan struct{}, 2)
+	repo.On("Rebuild", mock.Anything).Return(int64(0), nil).Run(func(mock.Arguments) { close(rebuilt) }).Once()
+	repo.On("Refresh", mock.Anything, int64(7)).Return(nil).Run(func(mock.Arguments) { refreshed <- struct{}{} }).Once()
+	repo.On("RefreshForLoan", mock.Anything, int64(3)).Return(nil).Run(func(mock.Arguments) { refreshed <- struct{}{} }).Once()
+
+	projector := NewProjector(NewService(repo, testLogger), hub, testLogger)
+	projector.Start(context.Background())
+	defer projector.Stop()
+	waitFor(t, rebuilt, "the initial rebuild")
+
+	hub.Broadcast(event.TypeCustomerUpdated, event.CustomerUpdatedEvent{Payload: event.CustomerEventPayload{CustomerID: 7}})
+	hub.Broadcast(event.TypeLoanPaymentReceived, event.LoanPaymentReceivedEvent{LoanID: 3, Amount: 110})
+	waitFor(t, refreshed, "the customer refresh")
+	waitFor(t, refreshed, "the loan refresh")
+
+	projector.Stop()
+	repo.AssertExpectations(t)
+}
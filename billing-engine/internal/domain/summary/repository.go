@@ -0,0 +1,22 @@
+package summary
+
+import "context"
+
+// Repository keeps the customer_loan_summary projection. Every refresh
+// recomputes whole rows from the customers, loans and loan_schedule tables,
+// so running one twice, or out of order, leaves the same result.
+type Repository interface {
+	// Get returns ErrNotFound when the customer has no row yet.
+	Get(ctx context.Context, customerID int64) (*CustomerLoanSummary, error)
+
+	// Refresh recomputes the row of a customer. It returns ErrNotFound for
+	// an unknown customer.
+	Refresh(ctx context.Context, customerID int64) error
+
+	// RefreshForLoan recomputes the row of the customer the loan is
+	// assigned to. A loan that is not assigned to anybody is ignored.
+	RefreshForLoan(ctx context.Context, loanID int64) error
+
+	// Rebuild recomputes every row and returns how many were written.
+	Rebuild(ctx context.Context) (int64, error)
+}
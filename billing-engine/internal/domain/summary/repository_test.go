@@ -0,0 +1,30 @@
+package summary
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) Get(ctx context.Context, customerID int64) (*CustomerLoanSummary, error) {
+	args := m.Called(ctx, customerID)
+	s, _ := args.Get(0).(*CustomerLoanSummary)
+	return s, args.Error(1)
+}
+
+func (m *MockRepository) Refresh(ctx context.Context, customerID int64) error {
+	return m.Called(ctx, customerID).Error(0)
+}
+
+func (m *MockRepository) RefreshForLoan(ctx context.Context, loanID int64) error {
+	return m.Called(ctx, loanID).Error(0)
+}
+
+func (m *MockRepository) Rebuild(ctx context.Context) (int64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(int64), args.Error(1)
+}
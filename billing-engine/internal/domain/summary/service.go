@@ -0,0 +1,71 @@
+package summary
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+)
+
+type Service interface {
+	// GetSummary returns the customer's row. A customer the projector has
+	// not caught up with yet, such as one created a moment ago, is computed
+	// on the spot and stored.
+	GetSummary(ctx context.Context, customerID int64) (*CustomerLoanSummary, error)
+
+	Refresh(ctx context.Context, customerID int64) error
+	RefreshForLoan(ctx context.Context, loanID int64) error
+	Rebuild(ctx context.Context) (int64, error)
+}
+
+var _ Service = (*service)(nil)
+
+type service struct {
+	repo   Repository
+	logger *slog.Logger
+}
+
+func NewService(repo Repository, logger *slog.Logger) Service {
+	if repo == nil {
+		panic("summary repository cannot be nil")
+	}
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+		logger.Warn("Warning: No logger provided to summary.NewService, using default stderr handler")
+	}
+	return &service{
+		repo:   repo,
+		logger: logger.With(slog.String("component", "summaryService")),
+	}
+}
+
+func (s *service) GetSummary(ctx context.Context, customerID int64) (*CustomerLoanSummary, error) {
+	sum, err := s.repo.Get(ctx, customerID)
+	if !errors.Is(err, apperrors.ErrNotFound) {
+		return sum, err
+	}
+
+	s.logger.DebugContext(ctx, "Summary not projected yet, computing it", slog.Int64("customerID", customerID))
+	if err := s.repo.Refresh(ctx, customerID); err != nil {
+		return nil, err
+	}
+	return s.repo.Get(ctx, customerID)
+}
+
+func (s *service) Refresh(ctx context.Context, customerID int64) error {
+	return s.repo.Refresh(ctx, customerID)
+}
+
+func (s *service) RefreshForLoan(ctx context.Context, loanID int64) error {
+	return s.repo.RefreshForLoan(ctx, loanID)
+}
+
+func (s *service) Rebuild(ctx context.Context) (int64, error) {
+	n, err := s.repo.Rebuild(ctx)
+	if err != nil {
+		return 0, err
+	}
+	s.logger.InfoContext(ctx, "Rebuilt customer loan summaries", slog.Int64("rows", n))
+	return n, nil
+}
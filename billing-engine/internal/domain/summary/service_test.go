@@ -0,0 +1,62 @@
+package summary
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func TestServiceGetSummary(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("reads the projected row", func(t *testing.T) {
+		repo := new(MockRepository)
+		repo.On("Get", ctx, int64(7)).Return(&CustomerLoanSummary{CustomerID: 7}, nil).Once()
+
+		s, err := NewService(repo, testLogger).GetSummary(ctx, 7)
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), s.CustomerID)
+		repo.AssertNotCalled(t, "Refresh", ctx, int64(7))
+	})
+
+	t.Run("computes a row the projector has not written yet", func(t *testing.T) {
+		repo := new(MockRepository)
+		repo.On("Get", ctx, int64(7)).Return(nil, apperrors.ErrNotFound).Once()
+		repo.On("Refresh", ctx, int64(7)).Return(nil).Once()
+		repo.On("Get", ctx, int64(7)).Return(&CustomerLoanSummary{CustomerID: 7}, nil).Once()
+
+		s, err := NewService(repo, testLogger).GetSummary(ctx, 7)
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), s.CustomerID)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("unknown customer", func(t *testing.T) {
+		repo := new(MockRepository)
+		repo.On("Get", ctx, int64(9)).Return(nil, apperrors.ErrNotFound).Once()
+		repo.On("Refresh", ctx, int64(9)).Return(apperrors.ErrNotFound).Once()
+
+		_, err := NewService(repo, testLogger).GetSummary(ctx, 9)
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+
+	t.Run("does not mask database errors", func(t *testing.T) {
+		repo := new(MockRepository)
+		repo.On("Get", ctx, int64(7)).Return(nil, apperrors.ErrDatabase).Once()
+
+		_, err := NewService(repo, testLogger).GetSummary(ctx, 7)
+
+		assert.ErrorIs(t, err, apperrors.ErrDatabase)
+		repo.AssertNotCalled(t, "Refresh", ctx, int64(7))
+	})
+}
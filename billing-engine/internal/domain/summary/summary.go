@@ -0,0 +1,42 @@
+package summary
+
+import (
+	"billing-engine/internal/domain/loan"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CustomerLoanSummary is a row of the customer_loan_summary projection: a
+// customer together with the state of their current loan, kept denormalized
+// so that it is read without joins. The loan fields are nil or zero for a
+// customer without a loan.
+type CustomerLoanSummary struct {
+	CustomerID       int64
+	CustomerPublicID uuid.UUID
+	Name             string
+	Address          string
+	Active           bool
+	IsDelinquent     bool
+
+	LoanID          *int64
+	LoanPublicID    *uuid.UUID
+	LoanStatus      *loan.LoanStatus
+	PrincipalAmount loan.Money
+	TotalLoanAmount loan.Money
+	// TotalPaid adds up what was paid on the installments; Outstanding is
+	// what is still due on the unpaid ones.
+	TotalPaid         loan.Money
+	Outstanding       loan.Money
+	InstallmentsPaid  int
+	InstallmentsTotal int
+	// NextDueDate and NextDueAmount describe the oldest unpaid installment,
+	// which may already be past due.
+	NextDueDate   *time.Time
+	NextDueAmount loan.Money
+	// DaysPastDue is the value the nightly delinquency job last stored.
+	DaysPastDue int
+
+	// RefreshedAt is when the row was last recomputed.
+	RefreshedAt time.Time
+}
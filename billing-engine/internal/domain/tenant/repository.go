@@ -0,0 +1,13 @@
+package tenant
+
+import "context"
+
+type Repository interface {
+	// GetSettings returns ErrNotFound when the tenant has no settings
+	// stored.
+	GetSettings(ctx context.Context, tenantID string) (*Settings, error)
+
+	// SaveSettings replaces the tenant's settings with s and stamps
+	// s.UpdatedAt.
+	SaveSettings(ctx context.Context, s *Settings) error
+}
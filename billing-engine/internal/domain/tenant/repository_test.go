@@ -0,0 +1,21 @@
+package tenant
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) GetSettings(ctx context.Context, tenantID string) (*Settings, error) {
+	args := m.Called(ctx, tenantID)
+	s, _ := args.Get(0).(*Settings)
+	return s, args.Error(1)
+}
+
+func (m *MockRepository) SaveSettings(ctx context.Context, s *Settings) error {
+	return m.Called(ctx, s).Error(0)
+}
@@ -0,0 +1,216 @@
+package tenant
+
+import (
+	"billing-engine/internal/domain/collections"
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"billing-engine/internal/pkg/scope"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Service reads and writes the settings of a tenant. A caller whose token
+// names a tenant may only reach that tenant's settings.
+type Service interface {
+	GetSettings(ctx context.Context, tenantID string) (*Settings, error)
+	// UpdateSettings replaces the tenant's overrides. A nil field goes back
+	// to the configured value.
+	UpdateSettings(ctx context.Context, tenantID string, overrides Overrides, updatedBy string) (*Settings, error)
+	// DelinquencyPolicy is the policy loans of the tenant are judged by.
+	DelinquencyPolicy(ctx context.Context, tenantID string) (loan.DelinquencyPolicy, error)
+	// RateLimit is the per-client rate and burst the API allows the
+	// tenant's callers.
+	RateLimit(ctx context.Context, tenantID string) (rps float64, burst int, err error)
+	// ReminderWindow is how many days after a loan reached an SMS or email
+	// step the tenant's reminder is still sent, zero meaning no limit.
+	ReminderWindow(ctx context.Context, tenantID string) (int, error)
+}
+
+var _ Service = (*service)(nil)
+
+// service keeps each tenant's settings in process for ttl, since the
+// delinquency job and the rate limiter read them all the time. An update
+// through this service drops the entry at once; an update made on another
+// instance shows up here once the entry expires. A read that raced with an
+// update does not store what it read: it notes the tenant's update counter
+// first and only stores the settings if no update came in since.
+type service struct {
+	repo     Repository
+	defaults Defaults
+	ttl      time.Duration
+	clock    clock.Clock
+	logger   *slog.Logger
+
+	mu          sync.Mutex
+	entries     map[string]cachedSettings
+	generations map[string]uint64
+}
+
+type cachedSettings struct {
+	settings Settings
+	expires  time.Time
+}
+
+// NewService applies the tenants' overrides on top of defaults. Settings
+// are cached for ttl by clk, nil meaning the wall clock; zero reads them
+// from repo every time.
+func NewService(repo Repository, defaults Defaults, ttl time.Duration, clk clock.Clock, logger *slog.Logger) Service {
+	if repo == nil {
+		panic("tenant repository cannot be nil")
+	}
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+		logger.Warn("Warning: No logger provided to tenant.NewService, using default stderr handler")
+	}
+	return &service{
+		repo:        repo,
+		defaults:    defaults,
+		ttl:         ttl,
+		clock:       clock.OrSystem(clk),
+		logger:      logger.With(slog.String("component", "tenantService")),
+		entries:     make(map[string]cachedSettings),
+		generations: make(map[string]uint64),
+	}
+}
+
+func (s *service) GetSettings(ctx context.Context, tenantID string) (*Settings, error) {
+	settings, err := s.settings(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// UpdateSettings drops the cached settings whether the save succeeded or
+// not, since a save that returned an error may still have been committed.
+func (s *service) UpdateSettings(ctx context.Context, tenantID string, overrides Overrides, updatedBy string) (*Settings, error) {
+	if err := authorize(ctx, tenantID); err != nil {
+		return nil, err
+	}
+	if err := overrides.Validate(); err != nil {
+		return nil, err
+	}
+	settings := &Settings{TenantID: tenantID, Overrides: overrides.clone(), UpdatedBy: strings.TrimSpace(updatedBy)}
+	defer s.invalidate(tenantID)
+	if err := s.repo.SaveSettings(ctx, settings); err != nil {
+		return nil, fmt.Errorf("failed to save settings of tenant %q: %w", tenantID, err)
+	}
+	policy := settings.DelinquencyPolicy(s.defaults)
+	rps, burst := settings.RateLimit(s.defaults)
+	s.logger.InfoContext(ctx, "Tenant settings updated", slog.String("tenantID", tenantID), slog.String("updatedBy", settings.UpdatedBy),
+		slog.Int("missedPayments", policy.MissedPayments), slog.Int("curePayments", policy.CurePayments),
+		slog.Int("graceDays", policy.GraceDays), slog.Float64("lateFee", policy.LateFee),
+		slog.Float64("rateLimitRPS", rps), slog.Int("rateLimitBurst", burst),
+		slog.Int("reminderWindowDays", settings.ReminderWindow(s.defaults)))
+	return settings, nil
+}
+
+func (s *service) DelinquencyPolicy(ctx context.Context, tenantID string) (loan.DelinquencyPolicy, error) {
+	settings, err := s.settings(ctx, tenantID)
+	if err != nil {
+		return loan.DelinquencyPolicy{}, err
+	}
+	return settings.DelinquencyPolicy(s.defaults), nil
+}
+
+func (s *service) RateLimit(ctx context.Context, tenantID string) (float64, int, error) {
+	settings, err := s.settings(ctx, tenantID)
+	if err != nil {
+		return 0, 0, err
+	}
+	rps, burst := settings.RateLimit(s.defaults)
+	return rps, burst, nil
+}
+
+func (s *service) ReminderWindow(ctx context.Context, tenantID string) (int, error) {
+	settings, err := s.settings(ctx, tenantID)
+	if err != nil {
+		return 0, err
+	}
+	return settings.ReminderWindow(s.defaults), nil
+}
+
+// settings returns a copy of the tenant's settings, from the cache when it
+// has them.
+func (s *service) settings(ctx context.Context, tenantID string) (Settings, error) {
+	if err := authorize(ctx, tenantID); err != nil {
+		return Settings{}, err
+	}
+	now := s.clock.Now()
+	s.mu.Lock()
+	entry, ok := s.entries[tenantID]
+	generation := s.generations[tenantID]
+	s.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		settings := entry.settings
+		settings.Overrides = entry.settings.Overrides.clone()
+		return settings, nil
+	}
+
+	stored, err := s.repo.GetSettings(ctx, tenantID)
+	switch {
+	case errors.Is(err, apperrors.ErrNotFound):
+		stored = &Settings{TenantID: tenantID}
+	case err != nil:
+		return Settings{}, fmt.Errorf("failed to get settings of tenant %q: %w", tenantID, err)
+	}
+	if s.ttl > 0 {
+		s.mu.Lock()
+		if s.generations[tenantID] == generation {
+			cached := *stored
+			cached.Overrides = stored.Overrides.clone()
+			s.entries[tenantID] = cachedSettings{settings: cached, expires: now.Add(s.ttl)}
+		}
+		s.mu.Unlock()
+	}
+	return *stored, nil
+}
+
+func (s *service) invalidate(tenantID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.generations[tenantID]++
+	delete(s.entries, tenantID)
+}
+
+// DelinquencySource is the delinquency policy of the tenant ctx is scoped
+// to, or of the default tenant, for the loan service and the delinquency
+// job.
+type DelinquencySource struct {
+	Service Service
+}
+
+var _ loan.DelinquencyPolicySource = DelinquencySource{}
+
+func (d DelinquencySource) Current(ctx context.Context) (loan.DelinquencyPolicy, error) {
+	return d.Service.DelinquencyPolicy(ctx, scope.TenantOrDefault(ctx))
+}
+
+// RateLimitSource is the rate limit of the tenant ctx is scoped to, or of
+// the default tenant, for the API's rate limiter.
+type RateLimitSource struct {
+	Service Service
+}
+
+func (r RateLimitSource) RateLimit(ctx context.Context) (float64, int, error) {
+	return r.Service.RateLimit(ctx, scope.TenantOrDefault(ctx))
+}
+
+// ReminderWindowSource is the reminder window of the tenant ctx is scoped
+// to, or of the default tenant, for the collections service.
+type ReminderWindowSource struct {
+	Service Service
+}
+
+var _ collections.ReminderWindowSource = ReminderWindowSource{}
+
+func (r ReminderWindowSource) ReminderWindow(ctx context.Context) (int, error) {
+	return r.Service.ReminderWindow(ctx, scope.TenantOrDefault(ctx))
+}
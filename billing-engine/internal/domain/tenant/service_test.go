@@ -0,0 +1,279 @@
+package tenant
+
+import (
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"billing-engine/internal/pkg/scope"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+var testNow = time.Date(2025, 3, 5, 9, 0, 0, 0, time.UTC)
+
+var testDefaults = Defaults{
+	Delinquency:        loan.DelinquencyPolicy{MissedPayments: 2, CurePayments: 2, GraceDays: 3},
+	RateLimitRPS:       10,
+	RateLimitBurst:     20,
+	ReminderWindowDays: 7,
+}
+
+func intPtr(v int) *int { return &v }
+
+func floatPtr(v float64) *float64 { return &v }
+
+func TestServiceAppliesOverrides(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("no settings stored keeps the configured values", func(t *testing.T) {
+		repo := new(MockRepository)
+		repo.On("GetSettings", ctx, DefaultTenant).Return(nil, fmt.Errorf("%w: tenant", apperrors.ErrNotFound))
+		svc := NewService(repo, testDefaults, time.Minute, clock.NewFake(testNow), testLogger)
+
+		policy, err := svc.DelinquencyPolicy(ctx, DefaultTenant)
+		require.NoError(t, err)
+		assert.Equal(t, testDefaults.Delinquency, policy)
+		rps, burst, err := svc.RateLimit(ctx, DefaultTenant)
+		require.NoError(t, err)
+		assert.Equal(t, 10.0, rps)
+		assert.Equal(t, 20, burst)
+		window, err := svc.ReminderWindow(ctx, DefaultTenant)
+		require.NoError(t, err)
+		assert.Equal(t, 7, window)
+		settings, err := svc.GetSettings(ctx, DefaultTenant)
+		require.NoError(t, err)
+		assert.Equal(t, &Settings{TenantID: DefaultTenant}, settings)
+		repo.AssertNumberOfCalls(t, "GetSettings", 1)
+	})
+
+	t.Run("stored overrides replace only the values they set", func(t *testing.T) {
+		repo := new(MockRepository)
+		repo.On("GetSettings", ctx, DefaultTenant).Return(&Settings{TenantID: DefaultTenant, Overrides: Overrides{MissedPayments: intPtr(3), RateLimitBurst: intPtr(5)}}, nil)
+		svc := NewService(repo, testDefaults, time.Minute, clock.NewFake(testNow), testLogger)
+
+		policy, err := svc.DelinquencyPolicy(ctx, DefaultTenant)
+		require.NoError(t, err)
+		assert.Equal(t, loan.DelinquencyPolicy{MissedPayments: 3, CurePayments: 2, GraceDays: 3}, policy)
+		rps, burst, err := svc.RateLimit(ctx, DefaultTenant)
+		require.NoError(t, err)
+		assert.Equal(t, 10.0, rps)
+		assert.Equal(t, 5, burst)
+	})
+
+	t.Run("grace period, late fee and reminder window", func(t *testing.T) {
+		repo := new(MockRepository)
+		repo.On("GetSettings", ctx, "acme").Return(&Settings{TenantID: "acme", Overrides: Overrides{
+			GraceDays: intPtr(0), LateFee: floatPtr(25), ReminderWindowDays: intPtr(14)}}, nil)
+		svc := NewService(repo, testDefaults, time.Minute, nil, testLogger)
+
+		policy, err := svc.DelinquencyPolicy(ctx, "acme")
+		require.NoError(t, err)
+		assert.Equal(t, loan.DelinquencyPolicy{MissedPayments: 2, CurePayments: 2, GraceDays: 0, LateFee: 25}, policy)
+		window, err := svc.ReminderWindow(ctx, "acme")
+		require.NoError(t, err)
+		assert.Equal(t, 14, window)
+	})
+
+	t.Run("each tenant has its own settings", func(t *testing.T) {
+		repo := new(MockRepository)
+		repo.On("GetSettings", ctx, DefaultTenant).Return(nil, fmt.Errorf("%w: tenant", apperrors.ErrNotFound))
+		repo.On("GetSettings", ctx, "acme").Return(&Settings{TenantID: "acme", Overrides: Overrides{MissedPayments: intPtr(5)}}, nil)
+		svc := NewService(repo, testDefaults, time.Minute, nil, testLogger)
+
+		policy, err := svc.DelinquencyPolicy(ctx, "acme")
+		require.NoError(t, err)
+		assert.Equal(t, 5, policy.MissedPayments)
+		policy, err = svc.DelinquencyPolicy(ctx, DefaultTenant)
+		require.NoError(t, err)
+		assert.Equal(t, 2, policy.MissedPayments)
+	})
+
+	t.Run("a caller of another tenant", func(t *testing.T) {
+		svc := NewService(new(MockRepository), testDefaults, time.Minute, nil, testLogger)
+		_, err := svc.GetSettings(scope.WithTenant(ctx, "globex"), "acme")
+		assert.ErrorIs(t, err, apperrors.ErrForbidden)
+	})
+
+	t.Run("invalid tenant ID", func(t *testing.T) {
+		svc := NewService(new(MockRepository), testDefaults, time.Minute, nil, testLogger)
+		for _, id := range []string{"", strings.Repeat("a", 65)} {
+			_, err := svc.DelinquencyPolicy(ctx, id)
+			assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+		}
+	})
+
+	t.Run("a failed read is not cached", func(t *testing.T) {
+		repo := new(MockRepository)
+		repo.On("GetSettings", ctx, DefaultTenant).Return(nil, fmt.Errorf("%w: down", apperrors.ErrDatabase)).Once()
+		repo.On("GetSettings", ctx, DefaultTenant).Return(&Settings{TenantID: DefaultTenant}, nil).Once()
+		svc := NewService(repo, testDefaults, time.Minute, nil, testLogger)
+
+		_, err := svc.DelinquencyPolicy(ctx, DefaultTenant)
+		assert.ErrorIs(t, err, apperrors.ErrDatabase)
+		_, err = svc.DelinquencyPolicy(ctx, DefaultTenant)
+		assert.NoError(t, err)
+	})
+}
+
+func TestServiceCache(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("entries expire after the TTL", func(t *testing.T) {
+		repo := new(MockRepository)
+		repo.On("GetSettings", ctx, DefaultTenant).Return(&Settings{TenantID: DefaultTenant}, nil)
+		clk := clock.NewFake(testNow)
+		svc := NewService(repo, testDefaults, time.Minute, clk, testLogger)
+
+		_, err := svc.GetSettings(ctx, DefaultTenant)
+		require.NoError(t, err)
+		_, err = svc.GetSettings(ctx, DefaultTenant)
+		require.NoError(t, err)
+		repo.AssertNumberOfCalls(t, "GetSettings", 1)
+
+		clk.Advance(time.Minute)
+		_, err = svc.GetSettings(ctx, DefaultTenant)
+		require.NoError(t, err)
+		repo.AssertNumberOfCalls(t, "GetSettings", 2)
+	})
+
+	t.Run("zero TTL reads every time", func(t *testing.T) {
+		repo := new(MockRepository)
+		repo.On("GetSettings", ctx, DefaultTenant).Return(&Settings{TenantID: DefaultTenant}, nil)
+		svc := NewService(repo, testDefaults, 0, nil, testLogger)
+
+		_, _ = svc.GetSettings(ctx, DefaultTenant)
+		_, _ = svc.GetSettings(ctx, DefaultTenant)
+		repo.AssertNumberOfCalls(t, "GetSettings", 2)
+	})
+
+	t.Run("an update drops the cached settings", func(t *testing.T) {
+		repo := new(MockRepository)
+		repo.On("GetSettings", ctx, DefaultTenant).Return(&Settings{TenantID: DefaultTenant}, nil).Once()
+		repo.On("SaveSettings", ctx, mock.AnythingOfType("*tenant.Settings")).Return(nil)
+		repo.On("GetSettings", ctx, DefaultTenant).Return(&Settings{TenantID: DefaultTenant, Overrides: Overrides{CurePayments: intPtr(4)}}, nil).Once()
+		svc := NewService(repo, testDefaults, time.Hour, nil, testLogger)
+
+		policy, err := svc.DelinquencyPolicy(ctx, DefaultTenant)
+		require.NoError(t, err)
+		assert.Equal(t, 2, policy.CurePayments)
+
+		_, err = svc.UpdateSettings(ctx, DefaultTenant, Overrides{CurePayments: intPtr(4)}, "ops")
+		require.NoError(t, err)
+		policy, err = svc.DelinquencyPolicy(ctx, DefaultTenant)
+		require.NoError(t, err)
+		assert.Equal(t, 4, policy.CurePayments)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("a read racing with an update is not cached", func(t *testing.T) {
+		repo := new(MockRepository)
+		svc := NewService(repo, testDefaults, time.Hour, nil, testLogger).(*service)
+		repo.On("GetSettings", ctx, DefaultTenant).Return(&Settings{TenantID: DefaultTenant}, nil).
+			Run(func(mock.Arguments) { svc.invalidate(DefaultTenant) })
+
+		_, err := svc.GetSettings(ctx, DefaultTenant)
+		require.NoError(t, err)
+		_, err = svc.GetSettings(ctx, DefaultTenant)
+		require.NoError(t, err)
+		repo.AssertNumberOfCalls(t, "GetSettings", 2)
+	})
+
+	t.Run("changing returned settings leaves the cache alone", func(t *testing.T) {
+		repo := new(MockRepository)
+		repo.On("GetSettings", ctx, DefaultTenant).Return(&Settings{TenantID: DefaultTenant, Overrides: Overrides{MissedPayments: intPtr(3)}}, nil)
+		svc := NewService(repo, testDefaults, time.Hour, nil, testLogger)
+
+		settings, err := svc.GetSettings(ctx, DefaultTenant)
+		require.NoError(t, err)
+		*settings.Overrides.MissedPayments = 9
+		policy, err := svc.DelinquencyPolicy(ctx, DefaultTenant)
+		require.NoError(t, err)
+		assert.Equal(t, 3, policy.MissedPayments)
+	})
+}
+
+func TestServiceUpdateSettings(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("saves the overrides", func(t *testing.T) {
+		repo := new(MockRepository)
+		rps := 2.5
+		repo.On("SaveSettings", ctx, &Settings{TenantID: DefaultTenant, Overrides: Overrides{RateLimitRPS: &rps}, UpdatedBy: "ops"}).Return(nil)
+		svc := NewService(repo, testDefaults, time.Minute, nil, testLogger)
+
+		settings, err := svc.UpdateSettings(ctx, DefaultTenant, Overrides{RateLimitRPS: &rps}, " ops ")
+		require.NoError(t, err)
+		assert.Equal(t, 2.5, *settings.Overrides.RateLimitRPS)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("rejects what the configuration would", func(t *testing.T) {
+		svc := NewService(new(MockRepository), testDefaults, time.Minute, nil, testLogger)
+		zero := 0.0
+		for _, o := range []Overrides{{MissedPayments: intPtr(0)}, {CurePayments: intPtr(-1)}, {RateLimitRPS: &zero}, {RateLimitBurst: intPtr(0)},
+			{GraceDays: intPtr(-1)}, {LateFee: floatPtr(-0.01)}, {LateFee: floatPtr(math.NaN())}, {ReminderWindowDays: intPtr(-1)}} {
+			_, err := svc.UpdateSettings(ctx, DefaultTenant, o, "ops")
+			assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+		}
+	})
+
+	t.Run("a caller of another tenant", func(t *testing.T) {
+		repo := new(MockRepository)
+		svc := NewService(repo, testDefaults, time.Minute, nil, testLogger)
+		_, err := svc.UpdateSettings(scope.WithTenant(ctx, "globex"), "acme", Overrides{}, "ops")
+		assert.ErrorIs(t, err, apperrors.ErrForbidden)
+		repo.AssertNotCalled(t, "SaveSettings", mock.Anything, mock.Anything)
+	})
+
+	t.Run("a failed save still drops the cached settings", func(t *testing.T) {
+		repo := new(MockRepository)
+		repo.On("GetSettings", ctx, DefaultTenant).Return(&Settings{TenantID: DefaultTenant}, nil)
+		repo.On("SaveSettings", ctx, mock.Anything).Return(errors.New("connection reset"))
+		svc := NewService(repo, testDefaults, time.Hour, nil, testLogger)
+
+		_, err := svc.GetSettings(ctx, DefaultTenant)
+		require.NoError(t, err)
+		_, err = svc.UpdateSettings(ctx, DefaultTenant, Overrides{MissedPayments: intPtr(3)}, "ops")
+		assert.Error(t, err)
+		_, err = svc.GetSettings(ctx, DefaultTenant)
+		require.NoError(t, err)
+		repo.AssertNumberOfCalls(t, "GetSettings", 2)
+	})
+}
+
+func TestSourcesFollowTheContextTenant(t *testing.T) {
+	repo := new(MockRepository)
+	repo.On("GetSettings", mock.Anything, DefaultTenant).Return(&Settings{TenantID: DefaultTenant, Overrides: Overrides{MissedPayments: intPtr(4)}}, nil)
+	repo.On("GetSettings", mock.Anything, "acme").Return(&Settings{TenantID: "acme", Overrides: Overrides{
+		MissedPayments: intPtr(6), RateLimitBurst: intPtr(3), ReminderWindowDays: intPtr(2)}}, nil)
+	svc := NewService(repo, testDefaults, time.Minute, nil, testLogger)
+	acme := scope.WithTenant(context.Background(), "acme")
+
+	var source loan.DelinquencyPolicySource = DelinquencySource{Service: svc}
+	policy, err := source.Current(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 4, policy.MissedPayments, "an unscoped context reads the default tenant")
+	policy, err = source.Current(acme)
+	require.NoError(t, err)
+	assert.Equal(t, 6, policy.MissedPayments)
+
+	_, burst, err := RateLimitSource{Service: svc}.RateLimit(acme)
+	require.NoError(t, err)
+	assert.Equal(t, 3, burst)
+	window, err := ReminderWindowSource{Service: svc}.ReminderWindow(acme)
+	require.NoError(t, err)
+	assert.Equal(t, 2, window)
+}
@@ -0,0 +1,155 @@
+package tenant
+
+import (
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/scope"
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// DefaultTenant is the tenant of callers whose token names none, and of the
+// loans and customers they create.
+const DefaultTenant = scope.DefaultTenant
+
+// maxTenantIDLength is the size of the tenant_id columns.
+const maxTenantIDLength = 64
+
+// Overrides replace configured values for one tenant. A nil field keeps the
+// configured value.
+type Overrides struct {
+	// MissedPayments and CurePayments replace the thresholds of the
+	// delinquency policy.
+	MissedPayments *int
+	CurePayments   *int
+	// RateLimitRPS and RateLimitBurst replace the per-client rate limit of
+	// the API.
+	RateLimitRPS   *float64
+	RateLimitBurst *int
+	// GraceDays and LateFee replace the grace period after a due date and
+	// the late fee charged once it is over.
+	GraceDays *int
+	LateFee   *float64
+	// ReminderWindowDays replaces how many days after a loan reached an
+	// SMS or email step its reminder is still sent.
+	ReminderWindowDays *int
+}
+
+// Settings are the overrides stored for a tenant. A tenant that never had
+// any has empty Overrides and a zero UpdatedAt.
+type Settings struct {
+	TenantID  string
+	Overrides Overrides
+	UpdatedBy string
+	UpdatedAt time.Time
+}
+
+// Defaults are the configured values that apply where a tenant has no
+// override.
+type Defaults struct {
+	Delinquency        loan.DelinquencyPolicy
+	RateLimitRPS       float64
+	RateLimitBurst     int
+	ReminderWindowDays int
+}
+
+// Validate rejects overrides the configuration would reject too.
+func (o Overrides) Validate() error {
+	if o.MissedPayments != nil && *o.MissedPayments < 1 {
+		return fmt.Errorf("%w: missed payments must be at least 1, got %d", apperrors.ErrInvalidArgument, *o.MissedPayments)
+	}
+	if o.CurePayments != nil && *o.CurePayments < 1 {
+		return fmt.Errorf("%w: cure payments must be at least 1, got %d", apperrors.ErrInvalidArgument, *o.CurePayments)
+	}
+	if o.RateLimitRPS != nil && !(*o.RateLimitRPS > 0) {
+		return fmt.Errorf("%w: rate limit must be above 0 requests per second, got %v", apperrors.ErrInvalidArgument, *o.RateLimitRPS)
+	}
+	if o.RateLimitBurst != nil && *o.RateLimitBurst < 1 {
+		return fmt.Errorf("%w: rate limit burst must be at least 1, got %d", apperrors.ErrInvalidArgument, *o.RateLimitBurst)
+	}
+	if o.GraceDays != nil && *o.GraceDays < 0 {
+		return fmt.Errorf("%w: grace days must not be negative, got %d", apperrors.ErrInvalidArgument, *o.GraceDays)
+	}
+	if o.LateFee != nil && (math.IsNaN(*o.LateFee) || math.IsInf(*o.LateFee, 0) || *o.LateFee < 0) {
+		return fmt.Errorf("%w: late fee must not be negative, got %v", apperrors.ErrInvalidArgument, *o.LateFee)
+	}
+	if o.ReminderWindowDays != nil && *o.ReminderWindowDays < 0 {
+		return fmt.Errorf("%w: reminder window must not be negative, got %d", apperrors.ErrInvalidArgument, *o.ReminderWindowDays)
+	}
+	return nil
+}
+
+// clone copies the values o points at, so that a cached copy cannot be
+// changed through the one handed out.
+func (o Overrides) clone() Overrides {
+	return Overrides{
+		MissedPayments:     clonePtr(o.MissedPayments),
+		CurePayments:       clonePtr(o.CurePayments),
+		RateLimitRPS:       clonePtr(o.RateLimitRPS),
+		RateLimitBurst:     clonePtr(o.RateLimitBurst),
+		GraceDays:          clonePtr(o.GraceDays),
+		LateFee:            clonePtr(o.LateFee),
+		ReminderWindowDays: clonePtr(o.ReminderWindowDays),
+	}
+}
+
+func clonePtr[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
+
+// DelinquencyPolicy is d's policy with the tenant's thresholds, grace
+// period and late fee in place of the configured ones.
+func (s Settings) DelinquencyPolicy(d Defaults) loan.DelinquencyPolicy {
+	policy := d.Delinquency
+	if s.Overrides.MissedPayments != nil {
+		policy.MissedPayments = *s.Overrides.MissedPayments
+	}
+	if s.Overrides.CurePayments != nil {
+		policy.CurePayments = *s.Overrides.CurePayments
+	}
+	if s.Overrides.GraceDays != nil {
+		policy.GraceDays = *s.Overrides.GraceDays
+	}
+	if s.Overrides.LateFee != nil {
+		policy.LateFee = *s.Overrides.LateFee
+	}
+	return policy
+}
+
+// RateLimit is the per-client rate and burst that apply to the tenant.
+func (s Settings) RateLimit(d Defaults) (float64, int) {
+	rps, burst := d.RateLimitRPS, d.RateLimitBurst
+	if s.Overrides.RateLimitRPS != nil {
+		rps = *s.Overrides.RateLimitRPS
+	}
+	if s.Overrides.RateLimitBurst != nil {
+		burst = *s.Overrides.RateLimitBurst
+	}
+	return rps, burst
+}
+
+// ReminderWindow is the reminder window in days that applies to the tenant.
+func (s Settings) ReminderWindow(d Defaults) int {
+	if s.Overrides.ReminderWindowDays != nil {
+		return *s.Overrides.ReminderWindowDays
+	}
+	return d.ReminderWindowDays
+}
+
+// authorize rejects a tenant ID the tenant_id columns cannot hold, and any
+// tenant but its own to a caller whose token names one.
+func authorize(ctx context.Context, tenantID string) error {
+	if tenantID == "" || len(tenantID) > maxTenantIDLength {
+		return fmt.Errorf("%w: tenant ID must be 1 to %d characters", apperrors.ErrInvalidArgument, maxTenantIDLength)
+	}
+	if own, scoped := scope.TenantFromContext(ctx); scoped && own != tenantID {
+		return fmt.Errorf("%w: the caller belongs to tenant %q, not %q", apperrors.ErrForbidden, own, tenantID)
+	}
+	return nil
+}
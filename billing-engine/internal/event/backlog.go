@@ -0,0 +1,77 @@
+package event
+
+import (
+	"billing-engine/internal/infrastructure/monitoring"
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const DefaultBacklogCheckInterval = time.Minute
+
+// BacklogMonitor keeps the event log backlog gauge current. A backlog that
+// only grows means RabbitMQ has been refusing or missing events and the log
+// needs a replay once the broker is back.
+type BacklogMonitor struct {
+	store    Store
+	interval time.Duration
+	logger   *slog.Logger
+	wg       sync.WaitGroup
+	cancel   context.CancelFunc
+}
+
+// NewBacklogMonitor counts the unpublished events in store every interval;
+// zero or less means DefaultBacklogCheckInterval.
+func NewBacklogMonitor(store Store, interval time.Duration, logger *slog.Logger) *BacklogMonitor {
+	if store == nil || logger == nil {
+		panic("event store and logger cannot be nil")
+	}
+	if interval <= 0 {
+		interval = DefaultBacklogCheckInterval
+	}
+	return &BacklogMonitor{store: store, interval: interval, logger: logger.With("component", "BacklogMonitor")}
+}
+
+// Start checks once right away and then every interval until Stop or until
+// ctx ends.
+func (m *BacklogMonitor) Start(ctx context.Context) {
+	loopCtx, cancel := context.WithCancel(ctx)
+	m.cancel = cancel
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+		for {
+			m.Check(loopCtx)
+			select {
+			case <-loopCtx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (m *BacklogMonitor) Stop() {
+	if m.cancel == nil {
+		return
+	}
+	m.cancel()
+	m.wg.Wait()
+}
+
+// Check updates the gauge from one count. When the count fails the gauge
+// keeps its last value.
+func (m *BacklogMonitor) Check(ctx context.Context) {
+	n, err := m.store.CountUnpublished(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			m.logger.WarnContext(ctx, "Failed to count unpublished events", slog.Any("error", err))
+		}
+		return
+	}
+	monitoring.SetEventLogBacklog(n)
+}
@@ -0,0 +1,25 @@
+package event
+
+import (
+	"billing-engine/internal/infrastructure/monitoring"
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBacklogMonitor(t *testing.T) {
+	store := &memoryStore{records: []Record{{EventID: "e-1"}, {EventID: "e-2"}}}
+	monitor := NewBacklogMonitor(store, 0, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	monitor.Check(context.Background())
+	assert.Equal(t, 2.0, testutil.ToFloat64(monitoring.Messaging.EventLogBacklog))
+
+	_ = store.MarkPublished(context.Background(), "e-1", replayNow)
+	monitor.Start(context.Background())
+	monitor.Stop()
+	assert.Equal(t, 1.0, testutil.ToFloat64(monitoring.Messaging.EventLogBacklog), "Start checks right away")
+}
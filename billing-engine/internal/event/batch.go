@@ -0,0 +1,206 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"events"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultBatchSize     = 100
+	DefaultFlushInterval = time.Second
+)
+
+// Message is one event handed to PublishBatch. Type is both the routing key
+// and the stream type, and EntityID the customer the event is about. Build
+// messages with the functions below, which fix the event ID so the body and
+// the AMQP message ID agree. A Private message carries a secret and only
+// goes to the broker: it is neither kept in the event log nor relayed to
+// the event stream.
+type Message struct {
+	Type       string
+	EventID    string
+	EntityID   int64
+	OccurredAt time.Time
+	Payload    events.Event
+	Private    bool
+}
+
+func CustomerCreatedMessage(e CustomerCreatedEvent) Message {
+	e.EventID = eventID(e.EventID)
+	return Message{Type: e.RoutingKey(), EventID: e.EventID, EntityID: e.Payload.CustomerID, OccurredAt: e.Timestamp, Payload: e}
+}
+
+func CustomerUpdatedMessage(e CustomerUpdatedEvent) Message {
+	e.EventID = eventID(e.EventID)
+	return Message{Type: e.RoutingKey(), EventID: e.EventID, EntityID: e.Payload.CustomerID, OccurredAt: e.Timestamp, Payload: e}
+}
+
+func DelinquencyChangedMessage(e CustomerDelinquencyChangedEvent) Message {
+	e.EventID = eventID(e.EventID)
+	return Message{Type: e.RoutingKey(), EventID: e.EventID, EntityID: e.CustomerID, OccurredAt: e.Timestamp, Payload: e}
+}
+
+func PreferencesChangedMessage(e CustomerPreferencesChangedEvent) Message {
+	e.EventID = eventID(e.EventID)
+	return Message{Type: e.RoutingKey(), EventID: e.EventID, EntityID: e.CustomerID, OccurredAt: e.Timestamp, Payload: e}
+}
+
+func RiskScoreRequestedMessage(e CustomerRiskScoreRequestedEvent) Message {
+	e.EventID = eventID(e.EventID)
+	return Message{Type: e.RoutingKey(), EventID: e.EventID, EntityID: e.CustomerID, OccurredAt: e.Timestamp, Payload: e}
+}
+
+// CustomerMergedMessage files the event under the target, which the source's
+// recorded events were moved to.
+func CustomerMergedMessage(e CustomerMergedEvent) Message {
+	e.EventID = eventID(e.EventID)
+	return Message{Type: e.RoutingKey(), EventID: e.EventID, EntityID: e.TargetID, OccurredAt: e.Timestamp, Payload: e}
+}
+
+func ContactsChangedMessage(e CustomerContactsChangedEvent) Message {
+	e.EventID = eventID(e.EventID)
+	return Message{Type: e.RoutingKey(), EventID: e.EventID, EntityID: e.CustomerID, OccurredAt: e.Timestamp, Payload: e}
+}
+
+// ContactVerificationMessage is private, since its body holds the code.
+func ContactVerificationMessage(e CustomerContactVerificationRequestedEvent) Message {
+	e.EventID = eventID(e.EventID)
+	return Message{Type: e.RoutingKey(), EventID: e.EventID, EntityID: e.CustomerID, OccurredAt: e.Timestamp, Payload: e, Private: true}
+}
+
+func LoanDelinquentMessage(e LoanDelinquentEvent) Message {
+	e.EventID = eventID(e.EventID)
+	return Message{Type: e.RoutingKey(), EventID: e.EventID, EntityID: e.CustomerID, OccurredAt: e.Timestamp, Payload: e}
+}
+
+func LoanPaidOffMessage(e LoanPaidOffEvent) Message {
+	e.EventID = eventID(e.EventID)
+	return Message{Type: e.RoutingKey(), EventID: e.EventID, EntityID: e.CustomerID, OccurredAt: e.Timestamp, Payload: e}
+}
+
+func ReminderDueMessage(e LoanReminderDueEvent) Message {
+	e.EventID = eventID(e.EventID)
+	return Message{Type: e.RoutingKey(), EventID: e.EventID, EntityID: e.CustomerID, OccurredAt: e.Timestamp, Payload: e}
+}
+
+func CollectionsTaskDueMessage(e CollectionsTaskDueEvent) Message {
+	e.EventID = eventID(e.EventID)
+	return Message{Type: e.RoutingKey(), EventID: e.EventID, EntityID: e.CustomerID, OccurredAt: e.Timestamp, Payload: e}
+}
+
+// Buffer collects messages from producers of many small events and publishes
+// them in batches of at most size: as soon as a batch is full and otherwise
+// every interval. A batch that fails is not retried; behind a
+// RecordingPublisher its events stay in the log as unpublished and can be
+// replayed.
+type Buffer struct {
+	pub      EventPublisher
+	size     int
+	interval time.Duration
+	logger   *slog.Logger
+
+	mu      sync.Mutex
+	pending []Message
+	stopped bool
+	// flushMu keeps batches in the order their messages were added.
+	flushMu sync.Mutex
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewBuffer publishes to pub. A size of zero or less means DefaultBatchSize
+// and an interval of zero or less DefaultFlushInterval.
+func NewBuffer(pub EventPublisher, size int, interval time.Duration, logger *slog.Logger) *Buffer {
+	if pub == nil || logger == nil {
+		panic("event publisher and logger cannot be nil")
+	}
+	if size <= 0 {
+		size = DefaultBatchSize
+	}
+	if interval <= 0 {
+		interval = DefaultFlushInterval
+	}
+	return &Buffer{
+		pub:      pub,
+		size:     size,
+		interval: interval,
+		logger:   logger.With("component", "EventBuffer"),
+	}
+}
+
+// Start flushes the buffer every interval until Stop.
+func (b *Buffer) Start() {
+	b.stop = make(chan struct{})
+	b.done = make(chan struct{})
+	go func() {
+		defer close(b.done)
+		ticker := time.NewTicker(b.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-b.stop:
+				return
+			case <-ticker.C:
+				b.flushAndLog(context.Background())
+			}
+		}
+	}()
+}
+
+// Stop ends the periodic flush and publishes what is still queued. Messages
+// added afterwards are published straight away.
+func (b *Buffer) Stop(ctx context.Context) error {
+	b.mu.Lock()
+	b.stopped = true
+	b.mu.Unlock()
+	if b.stop != nil {
+		close(b.stop)
+		<-b.done
+		b.stop = nil
+	}
+	return b.Flush(ctx)
+}
+
+// Add queues messages. Once a batch is full it is published on the caller's
+// goroutine, which keeps a fast producer from running ahead of the broker.
+func (b *Buffer) Add(ctx context.Context, messages ...Message) {
+	b.mu.Lock()
+	b.pending = append(b.pending, messages...)
+	flush := b.stopped || len(b.pending) >= b.size
+	b.mu.Unlock()
+	if flush {
+		b.flushAndLog(context.WithoutCancel(ctx))
+	}
+}
+
+// Flush publishes every queued message, in batches of at most size.
+func (b *Buffer) Flush(ctx context.Context) error {
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+
+	b.mu.Lock()
+	pending := b.pending
+	b.pending = nil
+	b.mu.Unlock()
+
+	var errs []error
+	for start := 0; start < len(pending); start += b.size {
+		batch := pending[start:min(start+b.size, len(pending))]
+		if err := b.pub.PublishBatch(ctx, batch); err != nil {
+			errs = append(errs, fmt.Errorf("failed to publish batch of %d events: %w", len(batch), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (b *Buffer) flushAndLog(ctx context.Context) {
+	if err := b.Flush(ctx); err != nil {
+		b.logger.ErrorContext(ctx, "Failed to publish buffered events", slog.Any("error", err))
+	}
+}
@@ -0,0 +1,253 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"events"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// batchRecorder keeps the batches it was given and fails them while err is
+// set.
+type batchRecorder struct {
+	EventPublisher
+	mu      sync.Mutex
+	batches [][]Message
+	err     error
+}
+
+func (p *batchRecorder) PublishBatch(_ context.Context, messages []Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.batches = append(p.batches, messages)
+	return p.err
+}
+
+func (p *batchRecorder) sizes() []int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	sizes := make([]int, len(p.batches))
+	for i, b := range p.batches {
+		sizes[i] = len(b)
+	}
+	return sizes
+}
+
+func updated(customerID int64) Message {
+	return CustomerUpdatedMessage(CustomerUpdatedEvent{Payload: CustomerEventPayload{CustomerID: customerID}})
+}
+
+func TestEventMessages(t *testing.T) {
+	m := CustomerCreatedMessage(CustomerCreatedEvent{Payload: CustomerEventPayload{CustomerID: 7}})
+	assert.Equal(t, TypeCustomerCreated, m.Type)
+	assert.Equal(t, int64(7), m.EntityID)
+	require.NotEmpty(t, m.EventID)
+	assert.Equal(t, m.EventID, m.Payload.(CustomerCreatedEvent).EventID, "the body carries the message ID")
+
+	m = DelinquencyChangedMessage(CustomerDelinquencyChangedEvent{EventID: "e-1", CustomerID: 3})
+	assert.Equal(t, TypeCustomerDelinquencyChanged, m.Type)
+	assert.Equal(t, "e-1", m.EventID)
+	assert.Equal(t, int64(3), m.EntityID)
+}
+
+func TestBuffer(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("publishes a full batch on add", func(t *testing.T) {
+		pub := &batchRecorder{}
+		buf := NewBuffer(pub, 2, time.Hour, discardLogger)
+
+		buf.Add(ctx, updated(1))
+		assert.Empty(t, pub.sizes())
+		buf.Add(ctx, updated(2), updated(3))
+		assert.Equal(t, []int{2, 1}, pub.sizes(), "a flush never exceeds the batch size")
+	})
+
+	t.Run("publishes on the interval", func(t *testing.T) {
+		pub := &batchRecorder{}
+		buf := NewBuffer(pub, 10, 10*time.Millisecond, discardLogger)
+		buf.Start()
+		defer buf.Stop(ctx)
+
+		buf.Add(ctx, updated(1))
+		assert.Eventually(t, func() bool { return len(pub.sizes()) == 1 }, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("stop publishes the rest and later adds go out at once", func(t *testing.T) {
+		pub := &batchRecorder{}
+		buf := NewBuffer(pub, 10, time.Hour, discardLogger)
+		buf.Start()
+
+		buf.Add(ctx, updated(1))
+		require.NoError(t, buf.Stop(ctx))
+		assert.Equal(t, []int{1}, pub.sizes())
+
+		buf.Add(ctx, updated(2))
+		assert.Equal(t, []int{1, 1}, pub.sizes())
+	})
+
+	t.Run("drops a failed batch", func(t *testing.T) {
+		pub := &batchRecorder{err: errors.New("broker down")}
+		buf := NewBuffer(pub, 10, time.Hour, discardLogger)
+
+		buf.Add(ctx, updated(1))
+		assert.ErrorContains(t, buf.Flush(ctx), "broker down")
+		assert.NoError(t, buf.Flush(ctx), "nothing is left to publish")
+	})
+}
+
+func TestRecordingPublisherBatch(t *testing.T) {
+	ctx := context.Background()
+	batch := []Message{updated(1), updated(2)}
+
+	t.Run("records and marks every message published", func(t *testing.T) {
+		store := &memoryStore{}
+		next := &batchRecorder{}
+		pub := NewRecordingPublisher(next, store, nil, discardLogger)
+
+		require.NoError(t, pub.PublishBatch(ctx, batch))
+
+		assert.Equal(t, []int{2}, next.sizes())
+		require.Len(t, store.records, 2)
+		for i, rec := range store.records {
+			assert.Equal(t, batch[i].EventID, rec.EventID)
+			assert.Equal(t, TypeCustomerUpdated, rec.Type)
+			assert.NotNil(t, rec.PublishedAt)
+		}
+	})
+
+	t.Run("publishes private messages without recording them", func(t *testing.T) {
+		store := &memoryStore{}
+		next := &batchRecorder{}
+		pub := NewRecordingPublisher(next, store, nil, discardLogger)
+		code := ContactVerificationMessage(CustomerContactVerificationRequestedEvent{CustomerID: 1, ContactID: 4, Code: "482913"})
+
+		require.NoError(t, pub.PublishBatch(ctx, []Message{updated(1), code}))
+
+		assert.Equal(t, []int{2}, next.sizes())
+		require.Len(t, store.records, 1)
+		assert.Equal(t, TypeCustomerUpdated, store.records[0].Type)
+	})
+
+	t.Run("numbers the messages of each customer", func(t *testing.T) {
+		store := &memoryStore{}
+		next := &batchRecorder{}
+		pub := NewRecordingPublisher(next, store, nil, discardLogger)
+		code := ContactVerificationMessage(CustomerContactVerificationRequestedEvent{CustomerID: 1, ContactID: 4, Code: "482913"})
+
+		require.NoError(t, pub.PublishBatch(ctx, []Message{updated(1), updated(2), code, updated(1)}))
+
+		sent := next.batches[0]
+		var sequences []int64
+		for _, m := range sent {
+			sequences = append(sequences, events.Sequence(mustMarshal(t, m.Payload)))
+		}
+		assert.Equal(t, []int64{1, 1, 0, 2}, sequences, "private messages are not numbered")
+		assert.Equal(t, []int64{1, 1, 2}, []int64{store.records[0].Sequence, store.records[1].Sequence, store.records[2].Sequence})
+		assert.Contains(t, string(store.records[2].Payload), `"sequence":2`, "the log keeps the numbered body")
+		assert.Zero(t, events.Sequence(mustMarshal(t, batch[0].Payload)), "the caller's messages are left as they were")
+	})
+
+	t.Run("publishes unnumbered when the log cannot number", func(t *testing.T) {
+		store := &memoryStore{seqErr: errors.New("database down")}
+		next := &batchRecorder{}
+		pub := NewRecordingPublisher(next, store, nil, discardLogger)
+
+		require.NoError(t, pub.PublishBatch(ctx, []Message{updated(1)}))
+
+		assert.Equal(t, []int{1}, next.sizes())
+		assert.Zero(t, events.Sequence(mustMarshal(t, next.batches[0][0].Payload)))
+		assert.Empty(t, store.records, "a number is only kept with its event")
+	})
+
+	t.Run("a failed append hands out no number", func(t *testing.T) {
+		store := &memoryStore{appendErr: errors.New("disk full")}
+		next := &batchRecorder{}
+		pub := NewRecordingPublisher(next, store, nil, discardLogger)
+
+		require.NoError(t, pub.PublishBatch(ctx, []Message{updated(1)}))
+		store.appendErr = nil
+		require.NoError(t, pub.PublishBatch(ctx, []Message{updated(1)}))
+
+		assert.Zero(t, events.Sequence(mustMarshal(t, next.batches[0][0].Payload)))
+		require.Len(t, store.records, 1)
+		assert.Equal(t, int64(1), store.records[0].Sequence, "numbers have no gap")
+	})
+
+	t.Run("leaves a failed batch unpublished", func(t *testing.T) {
+		store := &memoryStore{}
+		pub := NewRecordingPublisher(&batchRecorder{err: errors.New("broker down")}, store, nil, discardLogger)
+
+		assert.EqualError(t, pub.PublishBatch(ctx, batch), "broker down")
+
+		require.Len(t, store.records, 2)
+		for _, rec := range store.records {
+			assert.Nil(t, rec.PublishedAt)
+		}
+	})
+}
+
+// inFlightRecorder fails the test when two batches are in flight at once,
+// and keeps the sequences in the order they reached it.
+type inFlightRecorder struct {
+	EventPublisher
+	mu        sync.Mutex
+	inFlight  int
+	overlaps  int
+	sequences []int64
+}
+
+func (p *inFlightRecorder) PublishBatch(_ context.Context, messages []Message) error {
+	p.mu.Lock()
+	p.inFlight++
+	if p.inFlight > 1 {
+		p.overlaps++
+	}
+	for _, m := range messages {
+		_, body, _ := events.Marshal(m.Payload)
+		p.sequences = append(p.sequences, events.Sequence(body))
+	}
+	p.mu.Unlock()
+
+	time.Sleep(time.Millisecond)
+
+	p.mu.Lock()
+	p.inFlight--
+	p.mu.Unlock()
+	return nil
+}
+
+func TestRecordingPublisherPublishesCustomerEventsInOrder(t *testing.T) {
+	ctx := context.Background()
+	next := &inFlightRecorder{}
+	pub := NewRecordingPublisher(next, &memoryStore{}, nil, discardLogger)
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, pub.PublishBatch(ctx, []Message{updated(7)}))
+		}()
+	}
+	wg.Wait()
+
+	assert.Zero(t, next.overlaps, "one publish per customer at a time")
+	want := make([]int64, 20)
+	for i := range want {
+		want[i] = int64(i + 1)
+	}
+	assert.Equal(t, want, next.sequences, "published in sequence order")
+}
+
+func mustMarshal(t *testing.T, e events.Event) []byte {
+	t.Helper()
+	_, body, err := events.Marshal(e)
+	require.NoError(t, err)
+	return body
+}
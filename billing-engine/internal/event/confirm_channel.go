@@ -0,0 +1,53 @@
+package event
+
+import (
+	"context"
+	"fmt"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// publishChannel is a channel in confirm mode that also reports the messages
+// the broker returned as unroutable.
+type publishChannel interface {
+	Publish(ctx context.Context, exchange, routingKey string, msg amqp.Publishing) (confirmation, error)
+	Returns() <-chan amqp.Return
+	Close() error
+}
+
+type confirmation interface {
+	WaitContext(ctx context.Context) (bool, error)
+}
+
+type confirmChannel struct {
+	ch      *amqp.Channel
+	returns chan amqp.Return
+}
+
+// openConfirmChannel opens a channel on conn in confirm mode. The library
+// delivers returns on the connection's reader, so the returns buffer has to
+// hold one per message published on the channel or it would stall the
+// confirms behind it.
+func openConfirmChannel(conn *amqp.Connection, returns int) (publishChannel, error) {
+	ch, err := conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+	if err := ch.Confirm(false); err != nil {
+		ch.Close()
+		return nil, fmt.Errorf("failed to enable publisher confirms: %w", err)
+	}
+	return &confirmChannel{ch: ch, returns: ch.NotifyReturn(make(chan amqp.Return, returns))}, nil
+}
+
+func (c *confirmChannel) Publish(ctx context.Context, exchange, routingKey string, msg amqp.Publishing) (confirmation, error) {
+	return c.ch.PublishWithDeferredConfirmWithContext(ctx, exchange, routingKey, true, false, msg)
+}
+
+func (c *confirmChannel) Returns() <-chan amqp.Return {
+	return c.returns
+}
+
+func (c *confirmChannel) Close() error {
+	return c.ch.Close()
+}
@@ -0,0 +1,77 @@
+package event
+
+import (
+	"context"
+	"events"
+	"events/contract"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPublishedEventsMatchContract publishes every event notify-service
+// consumes the way the services do, through the event log that numbers
+// them and with each optional field set, and holds the bodies that reach the
+// broker to notify-service's examples.
+func TestPublishedEventsMatchContract(t *testing.T) {
+	ctx := context.Background()
+	at := time.Date(2025, 3, 4, 10, 0, 0, 0, time.Local)
+	loanID := int64(3)
+	payload := CustomerEventPayload{CustomerID: 7, Name: "Ayu Lestari", Address: "Jl. Merdeka No. 5, Bandung", Active: true, LoanID: &loanID, CreateDate: at, UpdatedAt: at}
+
+	published := map[string]func(EventPublisher) error{
+		events.RoutingKeyCustomerCreated: func(p EventPublisher) error {
+			return p.PublishCustomerCreated(ctx, CustomerCreatedEvent{Timestamp: at, Payload: payload})
+		},
+		events.RoutingKeyCustomerUpdated: func(p EventPublisher) error {
+			return p.PublishBatch(ctx, []Message{CustomerUpdatedMessage(CustomerUpdatedEvent{Timestamp: at, Payload: payload})})
+		},
+		events.RoutingKeyCustomerDelinquencyChanged: func(p EventPublisher) error {
+			return p.PublishCustomerDelinquencyChanged(ctx, CustomerDelinquencyChangedEvent{CustomerID: 7, LoanID: &loanID, NewStatus: true, Timestamp: at})
+		},
+		events.RoutingKeyCustomerPreferencesChanged: func(p EventPublisher) error {
+			return p.PublishBatch(ctx, []Message{PreferencesChangedMessage(CustomerPreferencesChangedEvent{
+				CustomerID: 7, PreferredChannel: "email", Language: "id", MarketingOptOut: true, UpdatedAt: at, Timestamp: at})})
+		},
+		events.RoutingKeyCustomerContactsChanged: func(p EventPublisher) error {
+			return p.PublishBatch(ctx, []Message{ContactsChangedMessage(CustomerContactsChangedEvent{CustomerID: 7,
+				Contacts: []ContactPayload{{ContactID: 12, Type: "phone", Value: "+6281234567890", Verified: true, Primary: true}}, UpdatedAt: at, Timestamp: at})})
+		},
+		events.RoutingKeyCustomerContactVerificationRequested: func(p EventPublisher) error {
+			return p.PublishBatch(ctx, []Message{ContactVerificationMessage(CustomerContactVerificationRequestedEvent{
+				CustomerID: 7, ContactID: 13, Type: "email", Value: "ayu.lestari@example.com", Code: "482913", ExpiresAt: at, Timestamp: at})})
+		},
+		events.RoutingKeyLoanDelinquent: func(p EventPublisher) error {
+			return p.PublishBatch(ctx, []Message{LoanDelinquentMessage(LoanDelinquentEvent{LoanID: 3, CustomerID: 7, DaysPastDue: 15, Bucket: "1-30", Timestamp: at})})
+		},
+		events.RoutingKeyLoanPaidOff: func(p EventPublisher) error {
+			return p.PublishBatch(ctx, []Message{LoanPaidOffMessage(LoanPaidOffEvent{LoanID: 3, CustomerID: 7, DocumentID: 12,
+				DocumentURL: "https://storage.example.com/billing/loans/3/documents/payoff.pdf", Timestamp: at})})
+		},
+		events.RoutingKeyLoanReminderDue: func(p EventPublisher) error {
+			return p.PublishBatch(ctx, []Message{ReminderDueMessage(LoanReminderDueEvent{LoanID: 3, CustomerID: 7, DaysPastDue: 8, Step: 7, Channel: "email", Timestamp: at})})
+		},
+	}
+	// notify-service subscribes to these, but billing-engine does not send
+	// them to the broker: loan creations and payments only go to the event
+	// stream. Publishing one means moving it to the table above.
+	unpublished := []string{events.RoutingKeyLoanCreated, events.RoutingKeyLoanPaymentReceived}
+
+	for _, key := range contract.RoutingKeys() {
+		t.Run(key, func(t *testing.T) {
+			publish, ok := published[key]
+			if !ok {
+				assert.Contains(t, unpublished, key, "notify-service consumes %s; publish it in this test", key)
+				return
+			}
+			broker := &fakeBroker{}
+			require.NoError(t, publish(NewRecordingPublisher(newTestRabbitPublisher(broker), &memoryStore{}, nil, discardLogger)))
+			require.Len(t, broker.published, 1)
+			assert.Equal(t, key, broker.keys[0])
+			assert.Equal(t, events.EventID(broker.published[0].Body), broker.published[0].MessageId, "the AMQP message ID is the event ID")
+			assert.NoError(t, contract.Match(key, broker.published[0].Body))
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Record is an event as kept in the event log. Payload is the message body
+// exactly as it was handed to the broker, so a replay sends the same bytes
+// under the same event ID.
+type Record struct {
+	ID      int64
+	EventID string
+	Type    string
+	// EntityID is the customer the event is about, and Sequence the number
+	// of the event among that customer's, zero for an event recorded before
+	// events were numbered. A merge moves the source's events to the target
+	// with the numbers they were sent with.
+	EntityID   int64
+	Sequence   int64
+	Payload    json.RawMessage
+	OccurredAt time.Time
+	// PublishedAt is nil while the broker has not accepted the event, for
+	// example because RabbitMQ was down when it was raised.
+	PublishedAt    *time.Time
+	ReplayCount    int
+	LastReplayedAt *time.Time
+}
+
+// ReplayFilter selects events from the log. Unset fields match every event;
+// Since is inclusive and Until exclusive. Results are ordered by ID, which is
+// the order the events were raised in.
+type ReplayFilter struct {
+	EntityID        *int64
+	Types           []string
+	Since           *time.Time
+	Until           *time.Time
+	UnpublishedOnly bool
+	Limit           int
+}
+
+// Store keeps every event the service publishes so that consumers that
+// missed some can be backfilled.
+type Store interface {
+	Append(ctx context.Context, r *Record) error
+	// AppendNumbered numbers r as the customer's next event, starting at 1,
+	// and appends it with the body encode returns for that number, all in
+	// one transaction. It holds the customer's number from handing it out
+	// until the event is in the log, so two instances cannot log a
+	// customer's events out of their order, and a failed append hands out
+	// no number.
+	AppendNumbered(ctx context.Context, r *Record, encode func(seq int64) (json.RawMessage, error)) error
+	MarkPublished(ctx context.Context, eventID string, at time.Time) error
+	List(ctx context.Context, f ReplayFilter) ([]Record, error)
+	// MarkReplayed counts a replay of the event and sets PublishedAt if the
+	// event had never been published.
+	MarkReplayed(ctx context.Context, id int64, at time.Time) error
+	// CountUnpublished counts the events PublishedAt is still nil for.
+	CountUnpublished(ctx context.Context) (int64, error)
+}
@@ -0,0 +1,182 @@
+package event
+
+import (
+	"encoding/json"
+	"events"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	TypeCustomerCreated            = events.RoutingKeyCustomerCreated
+	TypeCustomerUpdated            = events.RoutingKeyCustomerUpdated
+	TypeCustomerDelinquencyChanged = events.RoutingKeyCustomerDelinquencyChanged
+	TypeCustomerPreferencesChanged = events.RoutingKeyCustomerPreferencesChanged
+	TypeCustomerRiskScoreRequested = events.RoutingKeyCustomerRiskScoreRequested
+	TypeCustomerMerged             = events.RoutingKeyCustomerMerged
+	TypeCustomerContactsChanged    = events.RoutingKeyCustomerContactsChanged
+	TypeLoanCreated                = events.RoutingKeyLoanCreated
+	TypeLoanPaymentReceived        = events.RoutingKeyLoanPaymentReceived
+	TypeLoanDelinquent             = events.RoutingKeyLoanDelinquent
+	TypeLoanPaidOff                = events.RoutingKeyLoanPaidOff
+	TypeLoanReminderDue            = events.RoutingKeyLoanReminderDue
+	TypeCollectionsTaskDue         = events.RoutingKeyCollectionsTaskDue
+)
+
+// StreamEventTypes lists the event types clients may filter on.
+var StreamEventTypes = []string{
+	TypeCustomerCreated,
+	TypeCustomerUpdated,
+	TypeCustomerDelinquencyChanged,
+	TypeCustomerPreferencesChanged,
+	TypeCustomerRiskScoreRequested,
+	TypeCustomerMerged,
+	TypeCustomerContactsChanged,
+	TypeLoanCreated,
+	TypeLoanPaymentReceived,
+	TypeLoanDelinquent,
+	TypeLoanPaidOff,
+	TypeLoanReminderDue,
+	TypeCollectionsTaskDue,
+}
+
+func IsStreamEventType(t string) bool {
+	for _, known := range StreamEventTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Envelope is a domain event as relayed to stream subscribers. IDs increase
+// monotonically per process so clients can resume with Last-Event-ID.
+type Envelope struct {
+	ID         int64           `json:"id"`
+	Type       string          `json:"type"`
+	OccurredAt time.Time       `json:"occurredAt"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+// Hub fans domain events out to in-process subscribers and keeps a short
+// replay buffer for reconnecting clients.
+type Hub struct {
+	mu         sync.Mutex
+	nextID     int64
+	replay     []Envelope
+	replaySize int
+	bufferSize int
+	subs       map[*Subscription]struct{}
+	logger     *slog.Logger
+}
+
+type Subscription struct {
+	C      <-chan Envelope
+	ch     chan Envelope
+	types  map[string]bool
+	hub    *Hub
+	closed bool
+}
+
+func NewHub(bufferSize, replaySize int, logger *slog.Logger) *Hub {
+	if bufferSize <= 0 {
+		bufferSize = 64
+	}
+	if replaySize < 0 {
+		replaySize = 0
+	}
+	return &Hub{
+		replaySize: replaySize,
+		bufferSize: bufferSize,
+		subs:       map[*Subscription]struct{}{},
+		logger:     logger.With("component", "EventHub"),
+	}
+}
+
+// Broadcast assigns the next ID to the event and delivers it to every
+// matching subscriber. Subscribers that cannot keep up are disconnected
+// rather than blocking the publisher.
+func (h *Hub) Broadcast(eventType string, payload any) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		h.logger.Error("Failed to marshal stream event", "type", eventType, "error", err)
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.nextID++
+	env := Envelope{ID: h.nextID, Type: eventType, OccurredAt: time.Now().UTC(), Payload: body}
+	if h.replaySize > 0 {
+		h.replay = append(h.replay, env)
+		if len(h.replay) > h.replaySize {
+			h.replay = h.replay[len(h.replay)-h.replaySize:]
+		}
+	}
+
+	for sub := range h.subs {
+		if !sub.matches(env.Type) {
+			continue
+		}
+		select {
+		case sub.ch <- env:
+		default:
+			h.logger.Warn("Dropping slow event stream subscriber", "type", env.Type, "eventID", env.ID)
+			h.removeLocked(sub)
+		}
+	}
+}
+
+// Subscribe registers a subscriber for the given event types; an empty list
+// matches every type. Events newer than lastEventID still held in the replay
+// buffer are queued first.
+func (h *Hub) Subscribe(types []string, lastEventID int64) *Subscription {
+	ch := make(chan Envelope, h.bufferSize+h.replaySize)
+	sub := &Subscription{C: ch, ch: ch, types: map[string]bool{}, hub: h}
+	for _, t := range types {
+		if t = strings.TrimSpace(t); t != "" {
+			sub.types[t] = true
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if lastEventID > 0 {
+		for _, env := range h.replay {
+			if env.ID > lastEventID && sub.matches(env.Type) {
+				ch <- env
+			}
+		}
+	}
+	h.subs[sub] = struct{}{}
+	return sub
+}
+
+func (h *Hub) removeLocked(sub *Subscription) {
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	delete(h.subs, sub)
+	close(sub.ch)
+}
+
+func (h *Hub) subscriberCount() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs)
+}
+
+func (s *Subscription) matches(eventType string) bool {
+	return len(s.types) == 0 || s.types[eventType]
+}
+
+// Close unregisters the subscription and closes its channel.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.removeLocked(s)
+}
@@ -0,0 +1,73 @@
+package event
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestHub(bufferSize, replaySize int) *Hub {
+	return NewHub(bufferSize, replaySize, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestHubBroadcastFiltersByType(t *testing.T) {
+	hub := newTestHub(4, 0)
+	all := hub.Subscribe(nil, 0)
+	loansOnly := hub.Subscribe([]string{TypeLoanCreated}, 0)
+	defer all.Close()
+	defer loansOnly.Close()
+
+	hub.Broadcast(TypeCustomerCreated, map[string]int{"customerId": 1})
+	hub.Broadcast(TypeLoanCreated, LoanCreatedEvent{LoanID: 7})
+
+	first := <-all.C
+	assert.Equal(t, int64(1), first.ID)
+	assert.Equal(t, TypeCustomerCreated, first.Type)
+	assert.JSONEq(t, `{"customerId":1}`, string(first.Payload))
+	assert.Equal(t, TypeLoanCreated, (<-all.C).Type)
+
+	env := <-loansOnly.C
+	assert.Equal(t, int64(2), env.ID)
+	var payload LoanCreatedEvent
+	require.NoError(t, json.Unmarshal(env.Payload, &payload))
+	assert.Equal(t, int64(7), payload.LoanID)
+	assert.Empty(t, loansOnly.C)
+}
+
+func TestHubReplaysAfterLastEventID(t *testing.T) {
+	hub := newTestHub(4, 2)
+	hub.Broadcast(TypeLoanCreated, 1)
+	hub.Broadcast(TypeLoanPaymentReceived, 2)
+	hub.Broadcast(TypeLoanCreated, 3)
+
+	sub := hub.Subscribe(nil, 1)
+	defer sub.Close()
+
+	assert.Equal(t, int64(2), (<-sub.C).ID)
+	assert.Equal(t, int64(3), (<-sub.C).ID)
+	assert.Empty(t, sub.C)
+}
+
+func TestHubDropsSlowSubscriber(t *testing.T) {
+	hub := newTestHub(1, 0)
+	sub := hub.Subscribe(nil, 0)
+
+	hub.Broadcast(TypeLoanCreated, 1)
+	hub.Broadcast(TypeLoanCreated, 2)
+
+	assert.Equal(t, int64(1), (<-sub.C).ID)
+	_, open := <-sub.C
+	assert.False(t, open)
+	assert.Equal(t, 0, hub.subscriberCount())
+
+	sub.Close()
+}
+
+func TestIsStreamEventType(t *testing.T) {
+	assert.True(t, IsStreamEventType(TypeCustomerDelinquencyChanged))
+	assert.False(t, IsStreamEventType("loan.deleted"))
+}
@@ -0,0 +1,54 @@
+package event
+
+import (
+	"slices"
+	"sync"
+)
+
+// keyLocks serializes work per key, so that the events of one customer are
+// numbered and published one at a time while those of other customers go
+// ahead. Entries are dropped once nobody holds or waits for them.
+type keyLocks struct {
+	mu   sync.Mutex
+	held map[int64]*keyLock
+}
+
+type keyLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+func newKeyLocks() *keyLocks {
+	return &keyLocks{held: map[int64]*keyLock{}}
+}
+
+// lock blocks until it holds every key and returns the function that
+// releases them. Keys are taken in ascending order, which keeps two callers
+// with overlapping keys from waiting on each other.
+func (l *keyLocks) lock(keys ...int64) (unlock func()) {
+	keys = slices.Compact(slices.Sorted(slices.Values(keys)))
+	entries := make([]*keyLock, len(keys))
+	for i, key := range keys {
+		l.mu.Lock()
+		entry, ok := l.held[key]
+		if !ok {
+			entry = &keyLock{}
+			l.held[key] = entry
+		}
+		entry.refs++
+		l.mu.Unlock()
+
+		entry.mu.Lock()
+		entries[i] = entry
+	}
+	return func() {
+		for i := len(keys) - 1; i >= 0; i-- {
+			entries[i].mu.Unlock()
+			l.mu.Lock()
+			if entries[i].refs--; entries[i].refs == 0 {
+				delete(l.held, keys[i])
+			}
+			l.mu.Unlock()
+		}
+	}
+}
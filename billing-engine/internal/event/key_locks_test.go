@@ -0,0 +1,38 @@
+package event
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyLocks(t *testing.T) {
+	locks := newKeyLocks()
+	unlock := locks.lock(7, 3, 7)
+
+	acquired, released := make(chan struct{}), make(chan struct{})
+	go func() {
+		unlock := locks.lock(3)
+		close(acquired)
+		unlock()
+		close(released)
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("a held key was taken twice")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	locks.lock(4)()
+	unlock()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("a released key was not handed on")
+	}
+	<-released
+	locks.mu.Lock()
+	defer locks.mu.Unlock()
+	assert.Empty(t, locks.held, "released keys are forgotten")
+}
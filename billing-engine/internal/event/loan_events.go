@@ -0,0 +1,12 @@
+package event
+
+import "events"
+
+type (
+	LoanCreatedEvent         = events.LoanCreatedEvent
+	LoanPaymentReceivedEvent = events.LoanPaymentReceivedEvent
+	LoanDelinquentEvent      = events.LoanDelinquentEvent
+	LoanPaidOffEvent         = events.LoanPaidOffEvent
+	LoanReminderDueEvent     = events.LoanReminderDueEvent
+	CollectionsTaskDueEvent  = events.CollectionsTaskDueEvent
+)
@@ -2,36 +2,33 @@ package event
 
 import (
 	"context"
-	"time"
+	"events"
 )
 
-type CustomerEventPayload struct {
-	CustomerID   int64     `json:"customerId"`
-	Name         string    `json:"name"`
-	Address      string    `json:"address"`
-	IsDelinquent bool      `json:"isDelinquent"`
-	Active       bool      `json:"active"`
-	LoanID       *int64    `json:"loanId,omitempty"`
-	CreateDate   time.Time `json:"createDate"`
-	UpdatedAt    time.Time `json:"updatedAt"`
-}
+// The message bodies live in the shared events module so that notify-service
+// decodes exactly what is published here.
+type (
+	CustomerEventPayload = events.CustomerEventPayload
+	CustomerCreatedEvent = events.CustomerCreatedEvent
+	CustomerUpdatedEvent = events.CustomerUpdatedEvent
 
-type CustomerCreatedEvent struct {
-	Timestamp time.Time            `json:"timestamp"`
-	Payload   CustomerEventPayload `json:"payload"`
-}
+	CustomerPreferencesChangedEvent = events.CustomerPreferencesChangedEvent
+	CustomerRiskScoreRequestedEvent = events.CustomerRiskScoreRequestedEvent
+	CustomerMergedEvent             = events.CustomerMergedEvent
 
-type CustomerUpdatedEvent struct {
-	Timestamp time.Time            `json:"timestamp"`
-	Payload   CustomerEventPayload `json:"payload"`
-}
+	ContactPayload                            = events.ContactPayload
+	CustomerContactsChangedEvent              = events.CustomerContactsChangedEvent
+	CustomerContactVerificationRequestedEvent = events.CustomerContactVerificationRequestedEvent
+)
 
 func (p *RabbitMQEventPublisher) PublishCustomerCreated(ctx context.Context, event CustomerCreatedEvent) error {
-	return p.publish(ctx, routingKeyCustomerCreated, event)
+	event.EventID = eventID(event.EventID)
+	return p.publishEvent(ctx, event.EventID, event)
 }
 
 func (p *RabbitMQEventPublisher) PublishCustomerUpdated(ctx context.Context, event CustomerUpdatedEvent) error {
-	return p.publish(ctx, routingKeyCustomerUpdated, event)
+	event.EventID = eventID(event.EventID)
+	return p.publishEvent(ctx, event.EventID, event)
 }
 
 var _ EventPublisher = (*RabbitMQEventPublisher)(nil)
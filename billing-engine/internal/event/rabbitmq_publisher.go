@@ -1,24 +1,41 @@
 package event
 
 import (
+	"billing-engine/internal/infrastructure/monitoring"
+	"billing-engine/internal/pkg/retry"
 	"context"
 	"encoding/json"
+	"errors"
+	"events"
 	"fmt"
 	"log/slog"
 	"time"
 
+	"github.com/google/uuid"
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
 const (
-	routingKeyCustomerCreated = "customer.created"
-	routingKeyCustomerUpdated = "customer.updated"
-	publisherAppID            = "billing-engine"
+	publisherAppID = "billing-engine"
+
+	// maxPublishAttempts counts the first publish; messages the broker nacks
+	// are sent again until then, after publishRetryDelay and twice that.
+	maxPublishAttempts = 3
+	publishRetryDelay  = 200 * time.Millisecond
+	publishRetryJitter = 0.2
 )
 
+// ErrUnroutable means the broker returned a message because no queue is bound
+// for its routing key. Sending it again would not help.
+var ErrUnroutable = errors.New("message could not be routed to any queue")
+
+// errRefused fails an attempt in which the broker nacked messages.
+var errRefused = errors.New("broker refused messages")
+
 type RabbitMQEventPublisher struct {
-	conn         *amqp.Connection
+	openChannel  func(returns int) (publishChannel, error)
 	exchangeName string
+	retryDelay   time.Duration
 	logger       *slog.Logger
 }
 
@@ -26,16 +43,15 @@ type EventPublisher interface {
 	PublishCustomerDelinquencyChanged(ctx context.Context, event CustomerDelinquencyChangedEvent) error
 	PublishCustomerCreated(ctx context.Context, event CustomerCreatedEvent) error
 	PublishCustomerUpdated(ctx context.Context, event CustomerUpdatedEvent) error
+	// PublishBatch publishes messages together. An error means some of them
+	// may not have been published.
+	PublishBatch(ctx context.Context, messages []Message) error
 }
 
-type CustomerDelinquencyChangedEvent struct {
-	CustomerID int64     `json:"customerId"`
-	LoanID     *int64    `json:"loanId,omitempty"`
-	NewStatus  bool      `json:"newStatus"`
-	OldStatus  bool      `json:"oldStatus"`
-	Timestamp  time.Time `json:"timestamp"`
-}
+type CustomerDelinquencyChangedEvent = events.CustomerDelinquencyChangedEvent
 
+// NewRabbitMQEventPublisher publishes to exchangeName, which the topology
+// declared at startup must include.
 func NewRabbitMQEventPublisher(conn *amqp.Connection, exchangeName string, logger *slog.Logger) (EventPublisher, error) {
 	if conn == nil {
 		return nil, fmt.Errorf("RabbitMQ connection cannot be nil")
@@ -47,71 +63,195 @@ func NewRabbitMQEventPublisher(conn *amqp.Connection, exchangeName string, logge
 		panic("logger cannot be nil")
 	}
 
-	tempCh, err := conn.Channel()
-	if err != nil {
-		return nil, fmt.Errorf("failed to open temporary channel for exchange declaration: %w", err)
-	}
-	defer tempCh.Close()
-
-	err = tempCh.ExchangeDeclare(
-		exchangeName,
-		amqp.ExchangeTopic,
-		true,
-		false,
-		false,
-		false,
-		nil,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to declare exchange '%s': %w", exchangeName, err)
-	}
-	logger.Info("Ensured RabbitMQ exchange exists", "exchange", exchangeName, "type", amqp.ExchangeTopic)
-
 	return &RabbitMQEventPublisher{
-		conn:         conn,
+		openChannel:  func(returns int) (publishChannel, error) { return openConfirmChannel(conn, returns) },
 		exchangeName: exchangeName,
+		retryDelay:   publishRetryDelay,
 		logger:       logger.With("component", "RabbitMQEventPublisher", "exchange", exchangeName),
 	}, nil
 }
 
-func (p *RabbitMQEventPublisher) publish(ctx context.Context, routingKey string, payload interface{}) error {
-	logCtx := p.logger.With(slog.String("routingKey", routingKey))
-
-	channel, err := p.conn.Channel()
-	if err != nil {
-		logCtx.ErrorContext(ctx, "Failed to open RabbitMQ channel", slog.Any("error", err))
-		return fmt.Errorf("failed to open channel: %w", err)
+// eventID keeps an ID the caller already chose and generates one otherwise.
+// Consumers use it to recognise redelivered messages.
+func eventID(id string) string {
+	if id != "" {
+		return id
 	}
-	defer channel.Close()
+	return uuid.NewString()
+}
 
+// outgoing is a message encoded and ready for the broker.
+type outgoing struct {
+	routingKey string
+	eventID    string
+	body       []byte
+	headers    amqp.Table
+}
+
+func (p *RabbitMQEventPublisher) publish(ctx context.Context, routingKey, eventID string, payload interface{}, headers amqp.Table) error {
 	body, err := json.Marshal(payload)
 	if err != nil {
-		logCtx.ErrorContext(ctx, "Failed to marshal event payload to JSON", slog.Any("error", err))
+		p.logger.ErrorContext(ctx, "Failed to marshal event payload to JSON", slog.String("eventId", eventID), slog.Any("error", err))
 		return fmt.Errorf("failed to marshal event: %w", err)
 	}
+	return p.send(ctx, []outgoing{{routingKey: routingKey, eventID: eventID, body: body, headers: headers}})
+}
 
-	logCtx.DebugContext(ctx, "Publishing message", "bodySize", len(body))
-
-	err = channel.PublishWithContext(
-		ctx,
-		p.exchangeName,
-		routingKey,
-		false,
-		false,
-		amqp.Publishing{
-			ContentType:  "application/json",
-			DeliveryMode: amqp.Persistent,
-			Timestamp:    time.Now(),
-			Body:         body,
-			AppId:        publisherAppID,
+// publishEvent encodes e under its own routing key.
+func (p *RabbitMQEventPublisher) publishEvent(ctx context.Context, eventID string, e events.Event) error {
+	routingKey, body, err := events.Marshal(e)
+	if err != nil {
+		p.logger.ErrorContext(ctx, "Failed to marshal event payload to JSON", slog.String("eventId", eventID), slog.Any("error", err))
+		return err
+	}
+	return p.send(ctx, []outgoing{{routingKey: routingKey, eventID: eventID, body: body}})
+}
+
+func publishing(eventID string, body []byte, headers amqp.Table) amqp.Publishing {
+	return amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		Timestamp:    time.Now(),
+		Body:         body,
+		AppId:        publisherAppID,
+		MessageId:    eventID,
+		Headers:      headers,
+	}
+}
+
+// PublishBatch sends all messages on one channel and then waits for the
+// broker's confirmations together, instead of one round trip per event.
+func (p *RabbitMQEventPublisher) PublishBatch(ctx context.Context, messages []Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	batch := make([]outgoing, len(messages))
+	for i, m := range messages {
+		body, err := json.Marshal(m.Payload)
+		if err != nil {
+			p.logger.ErrorContext(ctx, "Failed to marshal event payload to JSON", slog.String("eventId", m.EventID), slog.Any("error", err))
+			return fmt.Errorf("failed to marshal event: %w", err)
+		}
+		batch[i] = outgoing{routingKey: m.Type, eventID: m.EventID, body: body}
+	}
+	return p.send(ctx, batch)
+}
+
+// send publishes every message as mandatory and returns once the broker has
+// confirmed all of them. Nacked messages are sent again on a fresh channel,
+// backing off, up to maxPublishAttempts in all. A returned message is
+// unroutable and fails the call without a retry, as does a channel that
+// cannot be used.
+func (p *RabbitMQEventPublisher) send(ctx context.Context, messages []outgoing) error {
+	pending := messages
+	policy := retry.Policy{
+		MaxAttempts: maxPublishAttempts, Initial: p.retryDelay, Jitter: publishRetryJitter,
+		OnRetry: func(attempt int, _ error, _ time.Duration) {
+			p.logger.WarnContext(ctx, "RabbitMQ refused messages, retrying", slog.Int("refused", len(pending)), slog.Int("attempt", attempt))
 		},
-	)
+	}
+	err := retry.Do(ctx, policy, func(ctx context.Context, _ int) error {
+		nacked, err := p.sendOnce(ctx, pending)
+		if err != nil {
+			return retry.Permanent(err)
+		}
+		pending = nacked
+		if len(nacked) > 0 {
+			return errRefused
+		}
+		return nil
+	})
+	switch {
+	case err == nil:
+		if len(messages) > 1 {
+			p.logger.InfoContext(ctx, "Successfully published batch", slog.Int("count", len(messages)))
+		}
+		return nil
+	case !errors.Is(err, errRefused):
+		return err
+	case ctx.Err() != nil:
+		return fmt.Errorf("gave up retrying refused messages: %w", ctx.Err())
+	}
+	for _, m := range pending {
+		monitoring.RecordEventPublish(m.routingKey, "failed")
+	}
+	p.logger.ErrorContext(ctx, "RabbitMQ refused messages, giving up", slog.Int("refused", len(pending)), slog.Int("attempts", maxPublishAttempts))
+	return fmt.Errorf("broker refused %d of %d messages after %d attempts", len(pending), len(messages), maxPublishAttempts)
+}
 
+// sendOnce publishes messages on a new channel and returns those the broker
+// nacked.
+func (p *RabbitMQEventPublisher) sendOnce(ctx context.Context, messages []outgoing) ([]outgoing, error) {
+	channel, err := p.openChannel(len(messages))
 	if err != nil {
-		logCtx.ErrorContext(ctx, "Failed to publish message to RabbitMQ", slog.Any("error", err))
-		return fmt.Errorf("failed to publish message: %w", err)
+		p.logger.ErrorContext(ctx, "Failed to open RabbitMQ channel", slog.Any("error", err))
+		p.recordAll(messages, "failed")
+		return nil, fmt.Errorf("failed to open channel: %w", err)
+	}
+	defer channel.Close()
+
+	confirms := make([]confirmation, len(messages))
+	for i, m := range messages {
+		p.logger.DebugContext(ctx, "Publishing message", "routingKey", m.routingKey, "eventId", m.eventID, "bodySize", len(m.body))
+		confirms[i], err = channel.Publish(ctx, p.exchangeName, m.routingKey, publishing(m.eventID, m.body, m.headers))
+		if err != nil {
+			p.logger.ErrorContext(ctx, "Failed to publish message to RabbitMQ", slog.String("routingKey", m.routingKey), slog.String("eventId", m.eventID), slog.Any("error", err))
+			p.recordAll(messages, "failed")
+			return nil, fmt.Errorf("failed to publish message: %w", err)
+		}
+	}
+
+	acked := make([]bool, len(messages))
+	for i, confirm := range confirms {
+		if acked[i], err = confirm.WaitContext(ctx); err != nil {
+			p.recordAll(messages, "failed")
+			return nil, fmt.Errorf("failed to wait for publisher confirm: %w", err)
+		}
+	}
+
+	// The broker sends basic.return before the ack of the message it
+	// returns, so every return has arrived once all confirms are in.
+	returned := map[string]bool{}
+	for drained := false; !drained; {
+		select {
+		case r := <-channel.Returns():
+			returned[r.MessageId] = true
+			p.logger.ErrorContext(ctx, "RabbitMQ returned unroutable message",
+				slog.String("routingKey", r.RoutingKey), slog.String("eventId", r.MessageId), slog.String("reason", r.ReplyText))
+		default:
+			drained = true
+		}
 	}
 
-	logCtx.InfoContext(ctx, "Successfully published message")
-	return nil
+	var nacked []outgoing
+	for i, m := range messages {
+		switch {
+		case returned[m.eventID]:
+			monitoring.RecordEventPublish(m.routingKey, "unroutable")
+		case !acked[i]:
+			monitoring.RecordEventPublish(m.routingKey, "nacked")
+			nacked = append(nacked, m)
+		default:
+			monitoring.RecordEventPublish(m.routingKey, "confirmed")
+			p.logger.InfoContext(ctx, "Successfully published message", slog.String("routingKey", m.routingKey), slog.String("eventId", m.eventID))
+		}
+	}
+	if len(returned) > 0 {
+		return nil, fmt.Errorf("%w: %d of %d messages", ErrUnroutable, len(returned), len(messages))
+	}
+	return nacked, nil
 }
+
+func (p *RabbitMQEventPublisher) recordAll(messages []outgoing, outcome string) {
+	for _, m := range messages {
+		monitoring.RecordEventPublish(m.routingKey, outcome)
+	}
+}
+
+// PublishRaw sends an event from the event log again. The x-replayed header
+// tells consumers it is a backfill; the message ID is the original one.
+func (p *RabbitMQEventPublisher) PublishRaw(ctx context.Context, eventType, eventID string, body json.RawMessage) error {
+	return p.publish(ctx, eventType, eventID, body, amqp.Table{"x-replayed": true})
+}
+
+var _ RawPublisher = (*RabbitMQEventPublisher)(nil)
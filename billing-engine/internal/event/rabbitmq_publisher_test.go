@@ -0,0 +1,118 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type ackConfirmation bool
+
+func (c ackConfirmation) WaitContext(context.Context) (bool, error) {
+	return bool(c), nil
+}
+
+// fakeBroker acks every message except the event IDs in nack, which it
+// refuses that many times, and returns those in unroutable.
+type fakeBroker struct {
+	nack       map[string]int
+	unroutable map[string]bool
+	channels   int
+	published  []amqp.Publishing
+	keys       []string
+}
+
+type fakeChannel struct {
+	broker  *fakeBroker
+	returns chan amqp.Return
+}
+
+func (b *fakeBroker) open(returns int) (publishChannel, error) {
+	b.channels++
+	return &fakeChannel{broker: b, returns: make(chan amqp.Return, returns)}, nil
+}
+
+func (c *fakeChannel) Publish(_ context.Context, _, routingKey string, msg amqp.Publishing) (confirmation, error) {
+	b := c.broker
+	b.published = append(b.published, msg)
+	b.keys = append(b.keys, routingKey)
+	if b.unroutable[msg.MessageId] {
+		c.returns <- amqp.Return{MessageId: msg.MessageId, RoutingKey: routingKey, ReplyText: "NO_ROUTE"}
+	}
+	if b.nack[msg.MessageId] > 0 {
+		b.nack[msg.MessageId]--
+		return ackConfirmation(false), nil
+	}
+	return ackConfirmation(true), nil
+}
+
+func (c *fakeChannel) Returns() <-chan amqp.Return { return c.returns }
+
+func (c *fakeChannel) Close() error { return nil }
+
+func newTestRabbitPublisher(b *fakeBroker) *RabbitMQEventPublisher {
+	return &RabbitMQEventPublisher{openChannel: b.open, exchangeName: "billing-engine", retryDelay: time.Millisecond, logger: discardLogger}
+}
+
+func TestRabbitMQEventPublisherConfirms(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("publishes a batch on one channel", func(t *testing.T) {
+		broker := &fakeBroker{}
+		pub := newTestRabbitPublisher(broker)
+
+		require.NoError(t, pub.PublishBatch(ctx, []Message{updated(1), updated(2)}))
+
+		assert.Equal(t, 1, broker.channels)
+		assert.Len(t, broker.published, 2)
+	})
+
+	t.Run("resends nacked messages", func(t *testing.T) {
+		broker := &fakeBroker{nack: map[string]int{"e-2": 1}}
+		pub := newTestRabbitPublisher(broker)
+
+		require.NoError(t, pub.PublishBatch(ctx, []Message{
+			CustomerUpdatedMessage(CustomerUpdatedEvent{EventID: "e-1"}),
+			CustomerUpdatedMessage(CustomerUpdatedEvent{EventID: "e-2"}),
+		}))
+
+		require.Len(t, broker.published, 3)
+		assert.Equal(t, "e-2", broker.published[2].MessageId, "only the refused message is sent again")
+		assert.Equal(t, 2, broker.channels)
+	})
+
+	t.Run("gives up after the last attempt", func(t *testing.T) {
+		broker := &fakeBroker{nack: map[string]int{"e-1": maxPublishAttempts}}
+		pub := newTestRabbitPublisher(broker)
+
+		err := pub.PublishCustomerUpdated(ctx, CustomerUpdatedEvent{EventID: "e-1"})
+
+		assert.ErrorContains(t, err, "broker refused 1 of 1 messages")
+		assert.Len(t, broker.published, maxPublishAttempts)
+	})
+
+	t.Run("fails unroutable messages without retrying", func(t *testing.T) {
+		broker := &fakeBroker{unroutable: map[string]bool{"e-1": true}}
+		pub := newTestRabbitPublisher(broker)
+
+		err := pub.PublishCustomerCreated(ctx, CustomerCreatedEvent{EventID: "e-1"})
+
+		assert.ErrorIs(t, err, ErrUnroutable)
+		assert.Len(t, broker.published, 1)
+	})
+
+	t.Run("reports a channel that cannot be opened", func(t *testing.T) {
+		pub := &RabbitMQEventPublisher{
+			openChannel:  func(int) (publishChannel, error) { return nil, errors.New("connection closed") },
+			exchangeName: "billing-engine",
+			logger:       discardLogger,
+		}
+
+		assert.ErrorContains(t, pub.PublishRaw(ctx, TypeCustomerUpdated, "e-1", []byte(`{}`)), "connection closed")
+	})
+}
@@ -0,0 +1,177 @@
+package event
+
+import (
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"encoding/json"
+	"events"
+	"fmt"
+	"log/slog"
+)
+
+// RecordingPublisher writes every event to the store before handing it to
+// next and marks it published once next accepted it. The log is written next
+// to the publish rather than in the transaction that changed the customer, so
+// it holds what was sent, not a guaranteed outbox.
+//
+// Each recorded event is numbered in its customer's sequence as it is
+// written, in one transaction of the store that holds the customer's
+// sequence row, so the numbers follow the order the events enter the log
+// even across instances. The customer's next event waits until the broker
+// has taken this one, so one instance publishes a customer's events in
+// sequence order. Events from different instances may still cross on the way
+// to the broker; the sequence in their bodies lets consumers put them back
+// in order and skip one older than what they already applied.
+type RecordingPublisher struct {
+	next   EventPublisher
+	store  Store
+	locks  *keyLocks
+	clock  clock.Clock
+	logger *slog.Logger
+}
+
+// NewRecordingPublisher wraps next. A nil next records events without
+// publishing them; they stay unpublished until replayed.
+func NewRecordingPublisher(next EventPublisher, store Store, clk clock.Clock, logger *slog.Logger) EventPublisher {
+	if store == nil || logger == nil {
+		panic("event store and logger cannot be nil")
+	}
+	return &RecordingPublisher{
+		next:   next,
+		store:  store,
+		locks:  newKeyLocks(),
+		clock:  clock.OrSystem(clk),
+		logger: logger.With("component", "RecordingPublisher"),
+	}
+}
+
+func (p *RecordingPublisher) PublishCustomerDelinquencyChanged(ctx context.Context, event CustomerDelinquencyChangedEvent) error {
+	event.EventID = eventID(event.EventID)
+	defer p.locks.lock(event.CustomerID)()
+	return p.record(ctx, DelinquencyChangedMessage(event), func(next EventPublisher, m Message) error {
+		return next.PublishCustomerDelinquencyChanged(ctx, m.Payload.(CustomerDelinquencyChangedEvent))
+	})
+}
+
+func (p *RecordingPublisher) PublishCustomerCreated(ctx context.Context, event CustomerCreatedEvent) error {
+	event.EventID = eventID(event.EventID)
+	defer p.locks.lock(event.Payload.CustomerID)()
+	return p.record(ctx, CustomerCreatedMessage(event), func(next EventPublisher, m Message) error {
+		return next.PublishCustomerCreated(ctx, m.Payload.(CustomerCreatedEvent))
+	})
+}
+
+func (p *RecordingPublisher) PublishCustomerUpdated(ctx context.Context, event CustomerUpdatedEvent) error {
+	event.EventID = eventID(event.EventID)
+	defer p.locks.lock(event.Payload.CustomerID)()
+	return p.record(ctx, CustomerUpdatedMessage(event), func(next EventPublisher, m Message) error {
+		return next.PublishCustomerUpdated(ctx, m.Payload.(CustomerUpdatedEvent))
+	})
+}
+
+// PublishBatch numbers and records every message but the private ones
+// before handing the batch to next, holding every customer in it until next
+// returns. A failed batch leaves all of its messages unpublished because the log cannot
+// tell which of them reached the broker; replaying them is safe since
+// consumers skip event IDs they already processed.
+func (p *RecordingPublisher) PublishBatch(ctx context.Context, messages []Message) error {
+	customers := make([]int64, 0, len(messages))
+	for _, m := range messages {
+		if !m.Private {
+			customers = append(customers, m.EntityID)
+		}
+	}
+	defer p.locks.lock(customers...)()
+
+	numbered := make([]Message, len(messages))
+	stored := make([]string, 0, len(messages))
+	for i, m := range messages {
+		numbered[i] = m
+		if m.Private {
+			continue
+		}
+		var ok bool
+		var err error
+		if numbered[i], ok, err = p.append(ctx, m); err != nil {
+			return err
+		}
+		if ok {
+			stored = append(stored, m.EventID)
+		}
+	}
+
+	if p.next == nil {
+		return nil
+	}
+	if err := p.next.PublishBatch(ctx, numbered); err != nil {
+		return err
+	}
+	for _, id := range stored {
+		p.markPublished(ctx, id)
+	}
+	return nil
+}
+
+// record never fails the publish because the log could not be written; the
+// event then goes out unnumbered and cannot be replayed. publish is handed
+// the message as it was recorded.
+func (p *RecordingPublisher) record(ctx context.Context, m Message, publish func(EventPublisher, Message) error) error {
+	m, stored, err := p.append(ctx, m)
+	if err != nil {
+		return err
+	}
+
+	if p.next == nil {
+		return nil
+	}
+	if err := publish(p.next, m); err != nil {
+		return err
+	}
+	if stored {
+		p.markPublished(ctx, m.EventID)
+	}
+	return nil
+}
+
+// append reports whether m made it into the log and returns it numbered in
+// its customer's sequence when its type carries one. Only an event that
+// cannot be encoded is an error.
+func (p *RecordingPublisher) append(ctx context.Context, m Message) (Message, bool, error) {
+	body, err := json.Marshal(m.Payload)
+	if err != nil {
+		return m, false, fmt.Errorf("failed to marshal event: %w", err)
+	}
+	occurredAt := m.OccurredAt
+	if occurredAt.IsZero() {
+		occurredAt = p.clock.Now()
+	}
+	rec := &Record{EventID: m.EventID, Type: m.Type, EntityID: m.EntityID, Payload: body, OccurredAt: occurredAt}
+
+	e, sequenced := m.Payload.(events.Sequenced)
+	if !sequenced {
+		err = p.store.Append(ctx, rec)
+	} else {
+		numbered := m
+		err = p.store.AppendNumbered(ctx, rec, func(seq int64) (json.RawMessage, error) {
+			numbered.Payload = e.WithSequence(seq)
+			return json.Marshal(numbered.Payload)
+		})
+		if err == nil {
+			m = numbered
+		}
+	}
+	if err != nil {
+		p.logger.ErrorContext(ctx, "Failed to record event, publishing it unnumbered and it cannot be replayed",
+			slog.String("eventId", m.EventID), slog.String("type", m.Type), slog.Any("error", err))
+		return m, false, nil
+	}
+	return m, true, nil
+}
+
+func (p *RecordingPublisher) markPublished(ctx context.Context, id string) {
+	if err := p.store.MarkPublished(ctx, id, p.clock.Now()); err != nil {
+		p.logger.WarnContext(ctx, "Failed to mark event published", slog.String("eventId", id), slog.Any("error", err))
+	}
+}
+
+var _ EventPublisher = (*RecordingPublisher)(nil)
@@ -0,0 +1,164 @@
+package event
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+const (
+	// MaxReplayEvents caps how many events one replay sends.
+	MaxReplayEvents = 10000
+
+	defaultReplayLimit = 1000
+)
+
+// RecordedEventTypes are the event types that go to the broker and are kept
+// in the event log.
+var RecordedEventTypes = []string{
+	TypeCustomerCreated,
+	TypeCustomerUpdated,
+	TypeCustomerDelinquencyChanged,
+	TypeCustomerPreferencesChanged,
+	TypeCustomerRiskScoreRequested,
+	TypeCustomerMerged,
+	TypeCustomerContactsChanged,
+	TypeLoanDelinquent,
+	TypeLoanPaidOff,
+	TypeLoanReminderDue,
+	TypeCollectionsTaskDue,
+}
+
+// RawPublisher sends an already encoded event to the broker under its
+// original ID, routed by its type.
+type RawPublisher interface {
+	PublishRaw(ctx context.Context, eventType, eventID string, body json.RawMessage) error
+}
+
+// ReplayReport says what a replay did. Failed holds the IDs of the events the
+// broker refused; they can be replayed again.
+type ReplayReport struct {
+	Matched  int
+	Replayed int
+	Failed   []string
+}
+
+type ReplayService interface {
+	// Find lists the events a replay with the same filter would send.
+	Find(ctx context.Context, f ReplayFilter) ([]Record, error)
+	// Replay publishes the matching events again, oldest first. Consumers see
+	// the original event IDs, so those that already processed an event skip
+	// it.
+	Replay(ctx context.Context, f ReplayFilter) (*ReplayReport, error)
+}
+
+var _ ReplayService = (*replayService)(nil)
+
+type replayService struct {
+	store     Store
+	publisher RawPublisher
+	clock     clock.Clock
+	logger    *slog.Logger
+}
+
+// NewReplayService builds the replay on store. publisher is nil when RabbitMQ
+// is not connected; Find still works and Replay is refused.
+func NewReplayService(store Store, publisher RawPublisher, clk clock.Clock, logger *slog.Logger) ReplayService {
+	if store == nil || logger == nil {
+		panic("event store and logger cannot be nil")
+	}
+	return &replayService{
+		store:     store,
+		publisher: publisher,
+		clock:     clock.OrSystem(clk),
+		logger:    logger.With(slog.String("component", "replayService")),
+	}
+}
+
+// normalizeFilter checks f and applies the default limit. A replay must be
+// narrowed by at least one criterion so that nobody resends the whole log by
+// accident.
+func normalizeFilter(f ReplayFilter) (ReplayFilter, error) {
+	types := make([]string, 0, len(f.Types))
+	for _, t := range f.Types {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		if !isRecordedEventType(t) {
+			return f, fmt.Errorf("%w: unknown event type %q", apperrors.ErrInvalidArgument, t)
+		}
+		types = append(types, t)
+	}
+	f.Types = types
+
+	if f.EntityID == nil && len(f.Types) == 0 && f.Since == nil && f.Until == nil && !f.UnpublishedOnly {
+		return f, fmt.Errorf("%w: select events by entity, type, time range or unpublished", apperrors.ErrInvalidArgument)
+	}
+	if f.Since != nil && f.Until != nil && !f.Since.Before(*f.Until) {
+		return f, fmt.Errorf("%w: since must be before until", apperrors.ErrInvalidArgument)
+	}
+	switch {
+	case f.Limit < 0 || f.Limit > MaxReplayEvents:
+		return f, fmt.Errorf("%w: limit must be between 1 and %d", apperrors.ErrInvalidArgument, MaxReplayEvents)
+	case f.Limit == 0:
+		f.Limit = defaultReplayLimit
+	}
+	return f, nil
+}
+
+func isRecordedEventType(t string) bool {
+	for _, known := range RecordedEventTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *replayService) Find(ctx context.Context, f ReplayFilter) ([]Record, error) {
+	f, err := normalizeFilter(f)
+	if err != nil {
+		return nil, err
+	}
+	return s.store.List(ctx, f)
+}
+
+func (s *replayService) Replay(ctx context.Context, f ReplayFilter) (*ReplayReport, error) {
+	f, err := normalizeFilter(f)
+	if err != nil {
+		return nil, err
+	}
+	if s.publisher == nil {
+		return nil, fmt.Errorf("%w: RabbitMQ is not connected", apperrors.ErrUnavailable)
+	}
+
+	records, err := s.store.List(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ReplayReport{Matched: len(records), Failed: []string{}}
+	s.logger.InfoContext(ctx, "Replaying events", slog.Int("matched", len(records)))
+	for _, rec := range records {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		logCtx := s.logger.With(slog.String("eventId", rec.EventID), slog.String("type", rec.Type))
+		if err := s.publisher.PublishRaw(ctx, rec.Type, rec.EventID, rec.Payload); err != nil {
+			logCtx.ErrorContext(ctx, "Failed to replay event", slog.Any("error", err))
+			report.Failed = append(report.Failed, rec.EventID)
+			continue
+		}
+		report.Replayed++
+		if err := s.store.MarkReplayed(ctx, rec.ID, s.clock.Now()); err != nil {
+			logCtx.WarnContext(ctx, "Failed to record replay", slog.Any("error", err))
+		}
+	}
+	s.logger.InfoContext(ctx, "Replay finished", slog.Int("replayed", report.Replayed), slog.Int("failed", len(report.Failed)))
+	return report, nil
+}
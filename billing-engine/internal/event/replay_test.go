@@ -0,0 +1,206 @@
+package event
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var replayNow = time.Date(2025, 4, 2, 9, 0, 0, 0, time.UTC)
+
+// memoryStore keeps the log in a slice and ignores the filter apart from the
+// entity, which is enough to drive the services.
+type memoryStore struct {
+	records   []Record
+	filters   []ReplayFilter
+	sequences map[int64]int64
+	appendErr error
+	seqErr    error
+}
+
+func (s *memoryStore) AppendNumbered(ctx context.Context, r *Record, encode func(seq int64) (json.RawMessage, error)) error {
+	if s.seqErr != nil {
+		return s.seqErr
+	}
+	if s.sequences == nil {
+		s.sequences = map[int64]int64{}
+	}
+	payload, err := encode(s.sequences[r.EntityID] + 1)
+	if err != nil {
+		return err
+	}
+	numbered := *r
+	numbered.Sequence, numbered.Payload = s.sequences[r.EntityID]+1, payload
+	if err := s.Append(ctx, &numbered); err != nil {
+		return err
+	}
+	s.sequences[r.EntityID]++
+	*r = numbered
+	return nil
+}
+
+func (s *memoryStore) Append(_ context.Context, r *Record) error {
+	if s.appendErr != nil {
+		return s.appendErr
+	}
+	r.ID = int64(len(s.records) + 1)
+	s.records = append(s.records, *r)
+	return nil
+}
+
+func (s *memoryStore) MarkPublished(_ context.Context, eventID string, at time.Time) error {
+	for i := range s.records {
+		if s.records[i].EventID == eventID {
+			s.records[i].PublishedAt = &at
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) List(_ context.Context, f ReplayFilter) ([]Record, error) {
+	s.filters = append(s.filters, f)
+	var out []Record
+	for _, r := range s.records {
+		if f.EntityID == nil || r.EntityID == *f.EntityID {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+func (s *memoryStore) MarkReplayed(_ context.Context, id int64, at time.Time) error {
+	r := &s.records[id-1]
+	r.ReplayCount++
+	r.LastReplayedAt = &at
+	if r.PublishedAt == nil {
+		r.PublishedAt = &at
+	}
+	return nil
+}
+
+func (s *memoryStore) CountUnpublished(context.Context) (int64, error) {
+	var n int64
+	for _, r := range s.records {
+		if r.PublishedAt == nil {
+			n++
+		}
+	}
+	return n, nil
+}
+
+type sentEvent struct {
+	Type, ID string
+	Body     json.RawMessage
+}
+
+type rawRecorder struct {
+	sent   []sentEvent
+	refuse string
+}
+
+func (p *rawRecorder) PublishRaw(_ context.Context, eventType, eventID string, body json.RawMessage) error {
+	if eventID == p.refuse {
+		return errors.New("broker refused")
+	}
+	p.sent = append(p.sent, sentEvent{eventType, eventID, body})
+	return nil
+}
+
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func TestRecordingPublisher(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("records and marks published", func(t *testing.T) {
+		store := &memoryStore{}
+		pub := NewRecordingPublisher(NewStreamingPublisher(nil, newTestHub(1, 0)), store, clock.NewFake(replayNow), discardLogger)
+
+		require.NoError(t, pub.PublishCustomerCreated(ctx, CustomerCreatedEvent{EventID: "e-1", Payload: CustomerEventPayload{CustomerID: 7}}))
+
+		require.Len(t, store.records, 1)
+		rec := store.records[0]
+		assert.Equal(t, TypeCustomerCreated, rec.Type)
+		assert.Equal(t, int64(7), rec.EntityID)
+		assert.Equal(t, replayNow, rec.OccurredAt)
+		require.NotNil(t, rec.PublishedAt)
+		var stored CustomerCreatedEvent
+		require.NoError(t, json.Unmarshal(rec.Payload, &stored))
+		assert.Equal(t, "e-1", stored.EventID)
+	})
+
+	t.Run("keeps failed publishes unpublished", func(t *testing.T) {
+		store := &memoryStore{}
+		pub := NewRecordingPublisher(failingPublisher{}, store, clock.NewFake(replayNow), discardLogger)
+
+		assert.EqualError(t, pub.PublishCustomerCreated(ctx, CustomerCreatedEvent{}), "broker down")
+
+		require.Len(t, store.records, 1)
+		assert.NotEmpty(t, store.records[0].EventID, "an ID is assigned before recording")
+		assert.Nil(t, store.records[0].PublishedAt)
+	})
+
+	t.Run("publishes when the log cannot be written", func(t *testing.T) {
+		store := &memoryStore{appendErr: errors.New("disk full")}
+		hub := newTestHub(1, 0)
+		sub := hub.Subscribe(nil, 0)
+		defer sub.Close()
+		pub := NewRecordingPublisher(NewStreamingPublisher(nil, hub), store, clock.NewFake(replayNow), discardLogger)
+
+		assert.NoError(t, pub.PublishCustomerDelinquencyChanged(ctx, CustomerDelinquencyChangedEvent{CustomerID: 3}))
+		assert.Equal(t, TypeCustomerDelinquencyChanged, (<-sub.C).Type)
+	})
+}
+
+func TestReplayService(t *testing.T) {
+	ctx := context.Background()
+	entity := int64(7)
+	newStore := func() *memoryStore {
+		return &memoryStore{records: []Record{
+			{ID: 1, EventID: "e-1", Type: TypeCustomerCreated, EntityID: 7, Payload: json.RawMessage(`{"eventId":"e-1"}`)},
+			{ID: 2, EventID: "e-2", Type: TypeCustomerUpdated, EntityID: 8, Payload: json.RawMessage(`{"eventId":"e-2"}`)},
+			{ID: 3, EventID: "e-3", Type: TypeCustomerDelinquencyChanged, EntityID: 7, Payload: json.RawMessage(`{"eventId":"e-3"}`)},
+		}}
+	}
+
+	t.Run("republishes the matching events", func(t *testing.T) {
+		store := newStore()
+		publisher := &rawRecorder{refuse: "e-3"}
+
+		report, err := NewReplayService(store, publisher, clock.NewFake(replayNow), discardLogger).Replay(ctx, ReplayFilter{EntityID: &entity})
+
+		require.NoError(t, err)
+		assert.Equal(t, &ReplayReport{Matched: 2, Replayed: 1, Failed: []string{"e-3"}}, report)
+		assert.Equal(t, []sentEvent{{TypeCustomerCreated, "e-1", json.RawMessage(`{"eventId":"e-1"}`)}}, publisher.sent)
+		assert.Equal(t, 1, store.records[0].ReplayCount)
+		assert.Equal(t, replayNow, *store.records[0].PublishedAt)
+		assert.Zero(t, store.records[2].ReplayCount)
+		assert.Equal(t, defaultReplayLimit, store.filters[0].Limit)
+	})
+
+	t.Run("needs a broker", func(t *testing.T) {
+		_, err := NewReplayService(newStore(), nil, nil, discardLogger).Replay(ctx, ReplayFilter{UnpublishedOnly: true})
+		assert.ErrorIs(t, err, apperrors.ErrUnavailable)
+	})
+
+	since := replayNow
+	for name, f := range map[string]ReplayFilter{
+		"no criteria":    {},
+		"unknown type":   {Types: []string{"loan.created"}},
+		"empty range":    {Since: &since, Until: &since},
+		"limit too high": {EntityID: &entity, Limit: MaxReplayEvents + 1},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewReplayService(newStore(), &rawRecorder{}, nil, discardLogger).Find(ctx, f)
+			assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+		})
+	}
+}
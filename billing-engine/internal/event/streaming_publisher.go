@@ -0,0 +1,62 @@
+package event
+
+import "context"
+
+// StreamingPublisher relays every successfully published customer event to
+// the hub so connected dashboards see it without consuming from the broker.
+type StreamingPublisher struct {
+	next EventPublisher
+	hub  *Hub
+}
+
+// NewStreamingPublisher wraps next. A nil next is allowed for deployments
+// running without RabbitMQ; events then only reach the stream.
+func NewStreamingPublisher(next EventPublisher, hub *Hub) EventPublisher {
+	return &StreamingPublisher{next: next, hub: hub}
+}
+
+func (p *StreamingPublisher) PublishCustomerDelinquencyChanged(ctx context.Context, event CustomerDelinquencyChangedEvent) error {
+	if p.next != nil {
+		if err := p.next.PublishCustomerDelinquencyChanged(ctx, event); err != nil {
+			return err
+		}
+	}
+	p.hub.Broadcast(TypeCustomerDelinquencyChanged, event)
+	return nil
+}
+
+func (p *StreamingPublisher) PublishCustomerCreated(ctx context.Context, event CustomerCreatedEvent) error {
+	if p.next != nil {
+		if err := p.next.PublishCustomerCreated(ctx, event); err != nil {
+			return err
+		}
+	}
+	p.hub.Broadcast(TypeCustomerCreated, event)
+	return nil
+}
+
+func (p *StreamingPublisher) PublishCustomerUpdated(ctx context.Context, event CustomerUpdatedEvent) error {
+	if p.next != nil {
+		if err := p.next.PublishCustomerUpdated(ctx, event); err != nil {
+			return err
+		}
+	}
+	p.hub.Broadcast(TypeCustomerUpdated, event)
+	return nil
+}
+
+func (p *StreamingPublisher) PublishBatch(ctx context.Context, messages []Message) error {
+	if p.next != nil {
+		if err := p.next.PublishBatch(ctx, messages); err != nil {
+			return err
+		}
+	}
+	for _, m := range messages {
+		if !m.Private {
+			p.hub.Broadcast(m.Type, m.Payload)
+		}
+	}
+	return nil
+}
+
+var _ EventPublisher = (*StreamingPublisher)(nil)
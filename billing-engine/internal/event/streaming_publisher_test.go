@@ -0,0 +1,72 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type failingPublisher struct {
+	EventPublisher
+}
+
+func (failingPublisher) PublishCustomerCreated(context.Context, CustomerCreatedEvent) error {
+	return errors.New("broker down")
+}
+
+func TestStreamingPublisher(t *testing.T) {
+	t.Run("Relays without a broker", func(t *testing.T) {
+		hub := newTestHub(4, 0)
+		sub := hub.Subscribe(nil, 0)
+		defer sub.Close()
+		pub := NewStreamingPublisher(nil, hub)
+
+		assert.NoError(t, pub.PublishCustomerDelinquencyChanged(context.Background(), CustomerDelinquencyChangedEvent{CustomerID: 3, NewStatus: true}))
+		assert.NoError(t, pub.PublishCustomerUpdated(context.Background(), CustomerUpdatedEvent{}))
+
+		assert.Equal(t, TypeCustomerDelinquencyChanged, (<-sub.C).Type)
+		assert.Equal(t, TypeCustomerUpdated, (<-sub.C).Type)
+	})
+
+	t.Run("Relays every message of a batch", func(t *testing.T) {
+		hub := newTestHub(4, 0)
+		sub := hub.Subscribe(nil, 0)
+		defer sub.Close()
+		pub := NewStreamingPublisher(nil, hub)
+
+		assert.NoError(t, pub.PublishBatch(context.Background(), []Message{
+			CustomerUpdatedMessage(CustomerUpdatedEvent{}),
+			CustomerCreatedMessage(CustomerCreatedEvent{}),
+		}))
+
+		assert.Equal(t, TypeCustomerUpdated, (<-sub.C).Type)
+		assert.Equal(t, TypeCustomerCreated, (<-sub.C).Type)
+	})
+
+	t.Run("Does not relay private messages", func(t *testing.T) {
+		hub := newTestHub(4, 0)
+		sub := hub.Subscribe(nil, 0)
+		defer sub.Close()
+		pub := NewStreamingPublisher(nil, hub)
+
+		assert.NoError(t, pub.PublishBatch(context.Background(), []Message{
+			ContactVerificationMessage(CustomerContactVerificationRequestedEvent{CustomerID: 3, Code: "482913"}),
+			ContactsChangedMessage(CustomerContactsChangedEvent{CustomerID: 3}),
+		}))
+
+		assert.Equal(t, TypeCustomerContactsChanged, (<-sub.C).Type)
+		assert.Empty(t, sub.C)
+	})
+
+	t.Run("Does not relay failed publishes", func(t *testing.T) {
+		hub := newTestHub(4, 0)
+		sub := hub.Subscribe(nil, 0)
+		defer sub.Close()
+		pub := NewStreamingPublisher(failingPublisher{}, hub)
+
+		assert.EqualError(t, pub.PublishCustomerCreated(context.Background(), CustomerCreatedEvent{}), "broker down")
+		assert.Empty(t, sub.C)
+	})
+}
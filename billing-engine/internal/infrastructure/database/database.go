@@ -0,0 +1,124 @@
+package database
+
+import (
+	"billing-engine/internal/config"
+	"billing-engine/internal/domain/agreement"
+	"billing-engine/internal/domain/collections"
+	"billing-engine/internal/domain/contact"
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/domain/directdebit"
+	"billing-engine/internal/domain/document"
+	"billing-engine/internal/domain/integrity"
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/domain/note"
+	"billing-engine/internal/domain/summary"
+	"billing-engine/internal/domain/tenant"
+	"billing-engine/internal/event"
+	"billing-engine/internal/infrastructure/database/postgres"
+	"billing-engine/internal/jobs"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+
+	// TenantEnforcementApplication leaves keeping tenants apart to the
+	// services; TenantEnforcementRLS has PostgreSQL row-level security
+	// check them as well, on every connection the repositories use.
+	TenantEnforcementApplication = "application"
+	TenantEnforcementRLS         = "rls"
+)
+
+// CustomerStore is what both backends provide for customers: the repository
+// used by the customer service and the bulk insert used by imports.
+type CustomerStore interface {
+	customer.CustomerRepository
+	customer.ImportRepository
+}
+
+// Repositories holds the storage the services are built on. All of them
+// share one connection pool, which Close releases.
+type Repositories struct {
+	Loans        loan.Repository
+	Snapshots    loan.SnapshotRepository
+	Customers    CustomerStore
+	Contacts     contact.Repository
+	Notes        note.Repository
+	Documents    document.Repository
+	Agreements   agreement.Repository
+	DirectDebits directdebit.Repository
+	Collections  collections.Repository
+	Events       event.Store
+	Summaries    summary.Repository
+	Integrity    integrity.Repository
+	Tenants      tenant.Repository
+	Archive      loan.ArchiveRepository
+	Partitions   loan.PartitionRepository
+	Jobs         jobs.Store
+	JobRuns      jobs.RunStore
+	JobLocks     jobs.Locker
+
+	close func()
+}
+
+func (r *Repositories) Close() {
+	if r.close != nil {
+		r.close()
+	}
+}
+
+// Open connects to the backend named by cfg.Driver. An empty driver means
+// PostgreSQL. The repositories stamp created_at and updated_at from clk.
+func Open(ctx context.Context, cfg config.DatabaseConfig, clk clock.Clock, logger *slog.Logger) (*Repositories, error) {
+	rls := strings.EqualFold(strings.TrimSpace(cfg.TenantEnforcement), TenantEnforcementRLS)
+	switch strings.ToLower(strings.TrimSpace(cfg.Driver)) {
+	case "", DriverPostgres:
+		return openPostgres(ctx, cfg, rls, clk, logger)
+	case DriverSQLite:
+		if rls {
+			return nil, fmt.Errorf("tenant enforcement %q needs the %q driver", TenantEnforcementRLS, DriverPostgres)
+		}
+		return openSQLite(ctx, cfg, clk, logger)
+	}
+	return nil, fmt.Errorf("unknown database driver %q, use %q or %q", cfg.Driver, DriverPostgres, DriverSQLite)
+}
+
+// openPostgres with rls names the caller's tenant on every connection any
+// repository takes from the pool.
+func openPostgres(ctx context.Context, cfg config.DatabaseConfig, rls bool, clk clock.Clock, logger *slog.Logger) (*Repositories, error) {
+	newPool := postgres.NewConnectionPool
+	if rls {
+		newPool = postgres.NewTenantConnectionPool
+	}
+	pool, err := newPool(ctx, cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	loans := postgres.NewLoanRepository(pool, clk, logger)
+	return &Repositories{
+		Loans:        loans,
+		Snapshots:    loans,
+		Customers:    postgres.NewCustomerRepository(pool, clk, logger),
+		Contacts:     postgres.NewContactRepository(pool, clk, logger),
+		Notes:        postgres.NewNoteRepository(pool, clk, logger),
+		Documents:    postgres.NewDocumentRepository(pool, clk, logger),
+		Agreements:   postgres.NewAgreementRepository(pool, logger),
+		DirectDebits: postgres.NewDirectDebitRepository(pool, clk, logger),
+		Collections:  postgres.NewCollectionsRepository(pool, clk, logger),
+		Events:       postgres.NewEventLogRepository(pool, logger),
+		Summaries:    postgres.NewSummaryRepository(pool, clk, logger),
+		Integrity:    postgres.NewIntegrityRepository(pool, logger),
+		Tenants:      postgres.NewTenantSettingsRepository(pool, clk, logger),
+		Archive:      loans,
+		Partitions:   loans,
+		Jobs:         postgres.NewJobRepository(pool, logger),
+		JobRuns:      postgres.NewJobRunRepository(pool, logger),
+		JobLocks:     postgres.NewJobLockRepository(pool, logger),
+		close:        pool.Close,
+	}, nil
+}
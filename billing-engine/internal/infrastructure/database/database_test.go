@@ -0,0 +1,29 @@
+package database
+
+import (
+	"billing-engine/internal/config"
+	"billing-engine/internal/pkg/clock"
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+
+func TestOpenRejectsUnknownDriver(t *testing.T) {
+	_, err := Open(context.Background(), config.DatabaseConfig{Driver: "mysql"}, clock.System(), testLogger)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown database driver "mysql"`)
+}
+
+func TestOpenPostgresNeedsURL(t *testing.T) {
+	for _, driver := range []string{"", "Postgres"} {
+		_, err := Open(context.Background(), config.DatabaseConfig{Driver: driver}, clock.System(), testLogger)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "database URL is empty")
+	}
+}
@@ -0,0 +1,128 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"billing-engine/internal/domain/agreement"
+	"billing-engine/internal/pkg/apperrors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	agreementColumns = `id, loan_id, document_id, hold_id, provider, envelope_id, status, COALESCE(sent_by, ''), sent_at, updated_at,
+               signed_at, COALESCE(disbursed_by, ''), disbursed_at`
+
+	createAgreementQuery = `
+        INSERT INTO loan_agreements (loan_id, document_id, hold_id, provider, envelope_id, status, sent_by, sent_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $8)
+        RETURNING id, updated_at`
+
+	getLatestAgreementQuery = `SELECT ` + agreementColumns + ` FROM loan_agreements WHERE loan_id = $1 ORDER BY sent_at DESC, id DESC LIMIT 1`
+
+	getAgreementByEnvelopeQuery = `SELECT ` + agreementColumns + ` FROM loan_agreements WHERE provider = $1 AND envelope_id = $2`
+
+	updateAgreementStatusQuery = `
+        UPDATE loan_agreements
+        SET status = $2, updated_at = $3, signed_at = CASE WHEN $2 = 'COMPLETED' THEN $3 END
+        WHERE id = $1 AND status = 'SENT'`
+
+	markAgreementDisbursedQuery = `
+        UPDATE loan_agreements SET disbursed_by = NULLIF($2, ''), disbursed_at = $3, updated_at = $3
+        WHERE id = $1 AND status = 'COMPLETED' AND disbursed_at IS NULL`
+)
+
+type AgreementRepository struct {
+	db     DBPool
+	logger *slog.Logger
+}
+
+var _ agreement.Repository = (*AgreementRepository)(nil)
+
+func NewAgreementRepository(db DBPool, logger *slog.Logger) *AgreementRepository {
+	if db == nil {
+		panic("DBPool cannot be nil for AgreementRepository")
+	}
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+		logger.Warn("Warning: No logger provided to NewAgreementRepository, using default stderr handler")
+	}
+	return &AgreementRepository{
+		db:     db,
+		logger: logger.With("component", "AgreementRepository"),
+	}
+}
+
+func (r *AgreementRepository) Create(ctx context.Context, a *agreement.Agreement) error {
+	err := r.db.QueryRow(ctx, createAgreementQuery, a.LoanID, a.DocumentID, a.HoldID, a.Provider, a.EnvelopeID, a.Status, a.SentBy, a.SentAt).
+		Scan(&a.ID, &a.UpdatedAt)
+	if err == nil {
+		return nil
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23503":
+			return fmt.Errorf("%w: loan %d", apperrors.ErrNotFound, a.LoanID)
+		case "23505":
+			return fmt.Errorf("%w: loan %d already has an open agreement, or envelope %s is recorded", apperrors.ErrConflict, a.LoanID, a.EnvelopeID)
+		}
+	}
+	r.logger.ErrorContext(ctx, "Failed to insert agreement", slog.Int64("loanID", a.LoanID), slog.Any("error", err))
+	return fmt.Errorf("%w: failed to insert agreement: %w", apperrors.ErrDatabase, err)
+}
+
+func (r *AgreementRepository) GetLatest(ctx context.Context, loanID int64) (*agreement.Agreement, error) {
+	return r.getAgreement(ctx, fmt.Sprintf("agreement of loan %d", loanID), getLatestAgreementQuery, loanID)
+}
+
+func (r *AgreementRepository) GetByEnvelope(ctx context.Context, provider, envelopeID string) (*agreement.Agreement, error) {
+	return r.getAgreement(ctx, fmt.Sprintf("envelope %s", envelopeID), getAgreementByEnvelopeQuery, provider, envelopeID)
+}
+
+func (r *AgreementRepository) getAgreement(ctx context.Context, what, query string, args ...any) (*agreement.Agreement, error) {
+	var a agreement.Agreement
+	if err := scanAgreement(r.db.QueryRow(ctx, query, args...), &a); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%w: %s", apperrors.ErrNotFound, what)
+		}
+		r.logger.ErrorContext(ctx, "Failed to get agreement", slog.String("agreement", what), slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to get agreement: %w", apperrors.ErrDatabase, err)
+	}
+	return &a, nil
+}
+
+func (r *AgreementRepository) UpdateStatus(ctx context.Context, agreementID int64, status agreement.Status, at time.Time) error {
+	tag, err := r.db.Exec(ctx, updateAgreementStatusQuery, agreementID, status, at)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to update agreement status", slog.Int64("agreementID", agreementID), slog.Any("error", err))
+		return fmt.Errorf("%w: failed to update agreement: %w", apperrors.ErrDatabase, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: agreement %d is no longer awaiting signature", apperrors.ErrConflict, agreementID)
+	}
+	return nil
+}
+
+func (r *AgreementRepository) MarkDisbursed(ctx context.Context, agreementID int64, disbursedBy string, at time.Time) error {
+	tag, err := r.db.Exec(ctx, markAgreementDisbursedQuery, agreementID, disbursedBy, at)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to record disbursement", slog.Int64("agreementID", agreementID), slog.Any("error", err))
+		return fmt.Errorf("%w: failed to update agreement: %w", apperrors.ErrDatabase, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: agreement %d is not signed or its loan was disbursed already", apperrors.ErrConflict, agreementID)
+	}
+	return nil
+}
+
+func scanAgreement(row pgx.Row, a *agreement.Agreement) error {
+	return row.Scan(&a.ID, &a.LoanID, &a.DocumentID, &a.HoldID, &a.Provider, &a.EnvelopeID, &a.Status, &a.SentBy, &a.SentAt, &a.UpdatedAt,
+		&a.SignedAt, &a.DisbursedBy, &a.DisbursedAt)
+}
@@ -0,0 +1,142 @@
+package postgres
+
+import (
+	"billing-engine/internal/domain/agreement"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var agreementRowColumns = []string{"id", "loan_id", "document_id", "hold_id", "provider", "envelope_id", "status", "sent_by", "sent_at", "updated_at",
+	"signed_at", "disbursed_by", "disbursed_at"}
+
+func setupAgreementRepo(t *testing.T) (context.Context, *AgreementRepository, pgxmock.PgxPoolIface) {
+	t.Helper()
+	mockPool, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("failed to open a stub database connection: %v", err)
+	}
+	return context.Background(), NewAgreementRepository(mockPool, logger), mockPool
+}
+
+func TestAgreementRepositoryCreate(t *testing.T) {
+	ctx, repo, mockPool := setupAgreementRepo(t)
+	defer mockPool.Close()
+
+	documentID, holdID := int64(7), int64(5)
+	mockPool.ExpectQuery(`INSERT INTO loan_agreements \(loan_id, document_id, hold_id, provider, envelope_id, status, sent_by, sent_at, updated_at\)`).
+		WithArgs(int64(42), &documentID, &holdID, "http", "env-1", agreement.StatusSent, "ops", testClock.Now()).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "updated_at"}).AddRow(int64(3), testClock.Now()))
+
+	a := &agreement.Agreement{LoanID: 42, DocumentID: &documentID, HoldID: &holdID, Provider: "http", EnvelopeID: "env-1",
+		Status: agreement.StatusSent, SentBy: "ops", SentAt: testClock.Now()}
+	err := repo.Create(ctx, a)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), a.ID)
+	assert.Equal(t, testClock.Now(), a.UpdatedAt)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestAgreementRepositoryCreateConstraintViolations(t *testing.T) {
+	tests := []struct {
+		code string
+		want error
+	}{
+		{code: "23503", want: apperrors.ErrNotFound},
+		{code: "23505", want: apperrors.ErrConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			ctx, repo, mockPool := setupAgreementRepo(t)
+			defer mockPool.Close()
+
+			mockPool.ExpectQuery(`INSERT INTO loan_agreements`).
+				WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+				WillReturnError(&pgconn.PgError{Code: tt.code})
+
+			err := repo.Create(ctx, &agreement.Agreement{LoanID: 42, Status: agreement.StatusSent})
+
+			assert.ErrorIs(t, err, tt.want)
+		})
+	}
+}
+
+func TestAgreementRepositoryGetLatest(t *testing.T) {
+	t.Run("returns the most recent agreement", func(t *testing.T) {
+		ctx, repo, mockPool := setupAgreementRepo(t)
+		defer mockPool.Close()
+
+		signedAt := testClock.Now()
+		mockPool.ExpectQuery(`FROM loan_agreements WHERE loan_id = \$1 ORDER BY sent_at DESC, id DESC LIMIT 1`).
+			WithArgs(int64(42)).
+			WillReturnRows(pgxmock.NewRows(agreementRowColumns).
+				AddRow(int64(3), int64(42), (*int64)(nil), (*int64)(nil), "http", "env-1", agreement.StatusCompleted, "", testClock.Now(), signedAt,
+					&signedAt, "", nil))
+
+		a, err := repo.GetLatest(ctx, 42)
+
+		require.NoError(t, err)
+		assert.Equal(t, agreement.StatusCompleted, a.Status)
+		assert.Nil(t, a.DocumentID)
+		assert.Equal(t, &signedAt, a.SignedAt)
+		assert.Nil(t, a.DisbursedAt)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("returns ErrNotFound for a loan without one", func(t *testing.T) {
+		ctx, repo, mockPool := setupAgreementRepo(t)
+		defer mockPool.Close()
+
+		mockPool.ExpectQuery(`FROM loan_agreements WHERE loan_id = \$1`).WithArgs(int64(42)).WillReturnError(pgx.ErrNoRows)
+
+		_, err := repo.GetLatest(ctx, 42)
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+}
+
+func TestAgreementRepositoryUpdateStatus(t *testing.T) {
+	t.Run("moves a sent agreement", func(t *testing.T) {
+		ctx, repo, mockPool := setupAgreementRepo(t)
+		defer mockPool.Close()
+
+		mockPool.ExpectExec(`UPDATE loan_agreements\s+SET status = \$2, updated_at = \$3, signed_at = CASE WHEN \$2 = 'COMPLETED' THEN \$3 END\s+WHERE id = \$1 AND status = 'SENT'`).
+			WithArgs(int64(3), agreement.StatusCompleted, testClock.Now()).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+		require.NoError(t, repo.UpdateStatus(ctx, 3, agreement.StatusCompleted, testClock.Now()))
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("returns ErrConflict once the agreement left SENT", func(t *testing.T) {
+		ctx, repo, mockPool := setupAgreementRepo(t)
+		defer mockPool.Close()
+
+		mockPool.ExpectExec(`UPDATE loan_agreements`).
+			WithArgs(int64(3), agreement.StatusVoided, testClock.Now()).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+		assert.ErrorIs(t, repo.UpdateStatus(ctx, 3, agreement.StatusVoided, testClock.Now()), apperrors.ErrConflict)
+	})
+}
+
+func TestAgreementRepositoryMarkDisbursed(t *testing.T) {
+	ctx, repo, mockPool := setupAgreementRepo(t)
+	defer mockPool.Close()
+
+	mockPool.ExpectExec(`UPDATE loan_agreements SET disbursed_by = NULLIF\(\$2, ''\), disbursed_at = \$3, updated_at = \$3\s+WHERE id = \$1 AND status = 'COMPLETED' AND disbursed_at IS NULL`).
+		WithArgs(int64(3), "ops", testClock.Now()).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	err := repo.MarkDisbursed(ctx, 3, "ops", testClock.Now())
+
+	assert.ErrorIs(t, err, apperrors.ErrConflict)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
@@ -0,0 +1,443 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"billing-engine/internal/domain/collections"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	collectionRuleColumns = `id, name, collector, COALESCE(bucket, ''), COALESCE(region, ''), min_outstanding, max_outstanding,
+        priority, active, created_at, updated_at`
+
+	assignmentColumns = `a.id, a.loan_id, COALESCE(a.customer_id, 0), a.collector, a.rule_id, a.status, COALESCE(a.resolution, ''),
+        a.assigned_at, a.resolved_at, a.created_at, a.updated_at`
+
+	collectionActionColumns = `id, assignment_id, loan_id, COALESCE(collector, ''), type, COALESCE(note, ''),
+        promised_amount, promised_date, created_at`
+
+	// loanOutstanding is what is still due on the unpaid installments of l.
+	loanOutstanding = `COALESCE((SELECT SUM(s.due_amount - s.paid_amount) FROM loan_schedule s WHERE s.loan_id = l.id AND s.status != 'PAID'), 0)`
+)
+
+type CollectionsRepository struct {
+	db     DBPool
+	clock  clock.Clock
+	logger *slog.Logger
+}
+
+var _ collections.Repository = (*CollectionsRepository)(nil)
+
+// NewCollectionsRepository builds the rules, assignments and actions
+// repository; clk stamps created_at and updated_at and nil means the wall
+// clock.
+func NewCollectionsRepository(db DBPool, clk clock.Clock, logger *slog.Logger) *CollectionsRepository {
+	if db == nil {
+		panic("DBPool cannot be nil for CollectionsRepository")
+	}
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+		logger.Warn("Warning: No logger provided to NewCollectionsRepository, using default stderr handler")
+	}
+	return &CollectionsRepository{
+		db:     db,
+		clock:  clock.OrSystem(clk),
+		logger: logger.With("component", "CollectionsRepository"),
+	}
+}
+
+func collectionRuleFields(r *collections.Rule) []any {
+	return []any{&r.ID, &r.Name, &r.Collector, &r.Bucket, &r.Region, &r.MinOutstanding, &r.MaxOutstanding,
+		&r.Priority, &r.Active, &r.CreatedAt, &r.UpdatedAt}
+}
+
+func assignmentFields(a *collections.Assignment) []any {
+	return []any{&a.ID, &a.LoanID, &a.CustomerID, &a.Collector, &a.RuleID, &a.Status, &a.Resolution,
+		&a.AssignedAt, &a.ResolvedAt, &a.CreatedAt, &a.UpdatedAt}
+}
+
+func collectionActionFields(a *collections.Action) []any {
+	return []any{&a.ID, &a.AssignmentID, &a.LoanID, &a.Collector, &a.Type, &a.Note,
+		&a.PromisedAmount, &a.PromisedDate, &a.CreatedAt}
+}
+
+func (r *CollectionsRepository) CreateRule(ctx context.Context, rule *collections.Rule) error {
+	query := `
+        INSERT INTO collection_rules (name, collector, bucket, region, min_outstanding, max_outstanding, priority, active, created_at, updated_at)
+        VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), $5, $6, $7, $8, $9, $9)
+        RETURNING id, created_at, updated_at`
+
+	err := r.db.QueryRow(ctx, query, rule.Name, rule.Collector, rule.Bucket, rule.Region, rule.MinOutstanding, rule.MaxOutstanding,
+		rule.Priority, rule.Active, r.clock.Now()).Scan(&rule.ID, &rule.CreatedAt, &rule.UpdatedAt)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to insert collection rule", slog.String("name", rule.Name), slog.Any("error", err))
+		return fmt.Errorf("%w: failed to insert collection rule: %w", apperrors.ErrDatabase, err)
+	}
+	return nil
+}
+
+func (r *CollectionsRepository) ListRules(ctx context.Context) ([]collections.Rule, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+collectionRuleColumns+` FROM collection_rules ORDER BY priority, id`)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query collection rules", slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to list collection rules: %w", apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	rules := []collections.Rule{}
+	for rows.Next() {
+		var rule collections.Rule
+		if err := rows.Scan(collectionRuleFields(&rule)...); err != nil {
+			return nil, fmt.Errorf("%w: failed to scan collection rule: %w", apperrors.ErrDatabase, err)
+		}
+		rules = append(rules, rule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: failed to iterate collection rules: %w", apperrors.ErrDatabase, err)
+	}
+	return rules, nil
+}
+
+func (r *CollectionsRepository) DeleteRule(ctx context.Context, ruleID int64) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM collection_rules WHERE id = $1`, ruleID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to delete collection rule", slog.Int64("ruleID", ruleID), slog.Any("error", err))
+		return fmt.Errorf("%w: failed to delete collection rule: %w", apperrors.ErrDatabase, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: collection rule %d", apperrors.ErrNotFound, ruleID)
+	}
+	return nil
+}
+
+func (r *CollectionsRepository) ListCandidates(ctx context.Context) ([]collections.Candidate, error) {
+	query := `
+        SELECT l.id, c.id, c.address, l.days_past_due, ` + loanOutstanding + `
+        FROM loans l
+        JOIN customers c ON c.loan_id = l.id
+        WHERE l.status != 'PAID_OFF' AND l.days_past_due > 0
+          AND NOT EXISTS (
+            SELECT 1 FROM collection_assignments a
+            WHERE a.loan_id = l.id AND a.status = 'OPEN'
+          )
+        ORDER BY l.id`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query collection candidates", slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to list collection candidates: %w", apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	candidates := []collections.Candidate{}
+	for rows.Next() {
+		var c collections.Candidate
+		if err := rows.Scan(&c.LoanID, &c.CustomerID, &c.Address, &c.DaysPastDue, &c.Outstanding); err != nil {
+			return nil, fmt.Errorf("%w: failed to scan collection candidate: %w", apperrors.ErrDatabase, err)
+		}
+		candidates = append(candidates, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: failed to iterate collection candidates: %w", apperrors.ErrDatabase, err)
+	}
+	return candidates, nil
+}
+
+func (r *CollectionsRepository) CreateAssignment(ctx context.Context, a *collections.Assignment) error {
+	query := `
+        INSERT INTO collection_assignments (loan_id, customer_id, collector, rule_id, status, assigned_at, created_at, updated_at)
+        VALUES ($1, NULLIF($2::bigint, 0), $3, $4, $5, $6, $7, $7)
+        RETURNING id, created_at, updated_at`
+
+	err := r.db.QueryRow(ctx, query, a.LoanID, a.CustomerID, a.Collector, a.RuleID, a.Status, a.AssignedAt, r.clock.Now()).
+		Scan(&a.ID, &a.CreatedAt, &a.UpdatedAt)
+	if err == nil {
+		return nil
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23503":
+			return fmt.Errorf("%w: loan %d", apperrors.ErrNotFound, a.LoanID)
+		case "23505":
+			return fmt.Errorf("%w: loan %d is already assigned", apperrors.ErrAlreadyExists, a.LoanID)
+		}
+	}
+	r.logger.ErrorContext(ctx, "Failed to insert collection assignment", slog.Int64("loanID", a.LoanID), slog.Any("error", err))
+	return fmt.Errorf("%w: failed to insert collection assignment: %w", apperrors.ErrDatabase, err)
+}
+
+func (r *CollectionsRepository) GetAssignment(ctx context.Context, assignmentID int64) (*collections.Assignment, error) {
+	var a collections.Assignment
+	err := r.db.QueryRow(ctx, `SELECT `+assignmentColumns+` FROM collection_assignments a WHERE a.id = $1`, assignmentID).
+		Scan(assignmentFields(&a)...)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%w: collection assignment %d", apperrors.ErrNotFound, assignmentID)
+		}
+		r.logger.ErrorContext(ctx, "Failed to get collection assignment", slog.Int64("assignmentID", assignmentID), slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to get collection assignment: %w", apperrors.ErrDatabase, err)
+	}
+	return &a, nil
+}
+
+func (r *CollectionsRepository) GetOpenAssignmentForLoan(ctx context.Context, loanID int64) (*collections.Assignment, error) {
+	var a collections.Assignment
+	err := r.db.QueryRow(ctx, `SELECT `+assignmentColumns+` FROM collection_assignments a WHERE a.loan_id = $1 AND a.status = 'OPEN'`, loanID).
+		Scan(assignmentFields(&a)...)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%w: loan %d has no open collection assignment", apperrors.ErrNotFound, loanID)
+		}
+		r.logger.ErrorContext(ctx, "Failed to get open collection assignment", slog.Int64("loanID", loanID), slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to get collection assignment: %w", apperrors.ErrDatabase, err)
+	}
+	return &a, nil
+}
+
+func (r *CollectionsRepository) ListOpenAssignments(ctx context.Context, collector string) ([]collections.Assignment, error) {
+	query := `
+        SELECT ` + assignmentColumns + `, COALESCE(c.name, ''), l.status, l.days_past_due, ` + loanOutstanding + `,
+            (SELECT MAX(x.created_at) FROM collection_actions x WHERE x.assignment_id = a.id)
+        FROM collection_assignments a
+        JOIN loans l ON l.id = a.loan_id
+        LEFT JOIN customers c ON c.id = a.customer_id
+        WHERE a.status = 'OPEN' AND ($1::text = '' OR a.collector = $1)
+        ORDER BY l.days_past_due DESC, a.id`
+
+	rows, err := r.db.Query(ctx, query, collector)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query open collection assignments", slog.String("collector", collector), slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to list collection assignments: %w", apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	assignments := []collections.Assignment{}
+	for rows.Next() {
+		var a collections.Assignment
+		fields := append(assignmentFields(&a), &a.CustomerName, &a.LoanStatus, &a.DaysPastDue, &a.Outstanding, &a.LastActionAt)
+		if err := rows.Scan(fields...); err != nil {
+			return nil, fmt.Errorf("%w: failed to scan collection assignment: %w", apperrors.ErrDatabase, err)
+		}
+		assignments = append(assignments, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: failed to iterate collection assignments: %w", apperrors.ErrDatabase, err)
+	}
+	return assignments, nil
+}
+
+func (r *CollectionsRepository) ResolveAssignment(ctx context.Context, assignmentID int64, resolution collections.Resolution, at time.Time) error {
+	query := `
+        UPDATE collection_assignments
+        SET status = $1, resolution = $2, resolved_at = $3, updated_at = $4
+        WHERE id = $5 AND status = $6`
+
+	tag, err := r.db.Exec(ctx, query, collections.AssignmentResolved, resolution, at, r.clock.Now(), assignmentID, collections.AssignmentOpen)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to resolve collection assignment", slog.Int64("assignmentID", assignmentID), slog.Any("error", err))
+		return fmt.Errorf("%w: failed to resolve collection assignment: %w", apperrors.ErrDatabase, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: collection assignment %d is no longer open", apperrors.ErrConflict, assignmentID)
+	}
+	return nil
+}
+
+func (r *CollectionsRepository) Reassign(ctx context.Context, assignmentID int64, collector string, action *collections.Action) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to begin transaction", slog.Any("error", err))
+		return fmt.Errorf("%w: failed to begin transaction: %w", apperrors.ErrDatabase, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var status collections.AssignmentStatus
+	err = tx.QueryRow(ctx, `SELECT status FROM collection_assignments WHERE id = $1 FOR UPDATE`, assignmentID).Scan(&status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("%w: collection assignment %d", apperrors.ErrNotFound, assignmentID)
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to lock collection assignment", slog.Int64("assignmentID", assignmentID), slog.Any("error", err))
+		return fmt.Errorf("%w: failed to lock collection assignment: %w", apperrors.ErrDatabase, err)
+	}
+	if status != collections.AssignmentOpen {
+		return fmt.Errorf("%w: collection assignment %d is no longer open", apperrors.ErrConflict, assignmentID)
+	}
+
+	now := r.clock.Now()
+	if _, err := tx.Exec(ctx, `UPDATE collection_assignments SET collector = $1, updated_at = $2 WHERE id = $3`, collector, now, assignmentID); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to reassign collection assignment", slog.Int64("assignmentID", assignmentID), slog.Any("error", err))
+		return fmt.Errorf("%w: failed to reassign collection assignment: %w", apperrors.ErrDatabase, err)
+	}
+	if err := insertCollectionAction(ctx, tx, action, now); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to record reassignment", slog.Int64("assignmentID", assignmentID), slog.Any("error", err))
+		return fmt.Errorf("%w: failed to record reassignment: %w", apperrors.ErrDatabase, err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%w: failed to commit reassignment: %w", apperrors.ErrDatabase, err)
+	}
+	return nil
+}
+
+func (r *CollectionsRepository) CreateAction(ctx context.Context, action *collections.Action) error {
+	err := insertCollectionAction(ctx, r.db, action, r.clock.Now())
+	if err == nil {
+		return nil
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		return fmt.Errorf("%w: collection assignment %d", apperrors.ErrNotFound, action.AssignmentID)
+	}
+	r.logger.ErrorContext(ctx, "Failed to insert collection action", slog.Int64("assignmentID", action.AssignmentID), slog.Any("error", err))
+	return fmt.Errorf("%w: failed to insert collection action: %w", apperrors.ErrDatabase, err)
+}
+
+type queryRower interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+func insertCollectionAction(ctx context.Context, db queryRower, a *collections.Action, createdAt time.Time) error {
+	query := `
+        INSERT INTO collection_actions (assignment_id, loan_id, collector, type, note, promised_amount, promised_date, created_at)
+        VALUES ($1, $2, NULLIF($3, ''), $4, NULLIF($5, ''), $6, $7, $8)
+        RETURNING id, created_at`
+
+	return db.QueryRow(ctx, query, a.AssignmentID, a.LoanID, a.Collector, a.Type, a.Note, a.PromisedAmount, a.PromisedDate, createdAt).
+		Scan(&a.ID, &a.CreatedAt)
+}
+
+func (r *CollectionsRepository) ListActions(ctx context.Context, assignmentID int64) ([]collections.Action, error) {
+	query := `SELECT ` + collectionActionColumns + ` FROM collection_actions WHERE assignment_id = $1 ORDER BY created_at DESC, id DESC`
+
+	rows, err := r.db.Query(ctx, query, assignmentID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query collection actions", slog.Int64("assignmentID", assignmentID), slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to list collection actions: %w", apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	actions := []collections.Action{}
+	for rows.Next() {
+		var a collections.Action
+		if err := rows.Scan(collectionActionFields(&a)...); err != nil {
+			return nil, fmt.Errorf("%w: failed to scan collection action: %w", apperrors.ErrDatabase, err)
+		}
+		actions = append(actions, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: failed to iterate collection actions: %w", apperrors.ErrDatabase, err)
+	}
+	return actions, nil
+}
+
+func (r *CollectionsRepository) ListEscalationSteps(ctx context.Context) ([]collections.EscalationStep, error) {
+	rows, err := r.db.Query(ctx, `SELECT days_past_due, action, created_at FROM escalation_steps ORDER BY days_past_due`)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query escalation steps", slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to list escalation steps: %w", apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	steps := []collections.EscalationStep{}
+	for rows.Next() {
+		var s collections.EscalationStep
+		if err := rows.Scan(&s.DaysPastDue, &s.Action, &s.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%w: failed to scan escalation step: %w", apperrors.ErrDatabase, err)
+		}
+		steps = append(steps, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: failed to iterate escalation steps: %w", apperrors.ErrDatabase, err)
+	}
+	return steps, nil
+}
+
+func (r *CollectionsRepository) ReplaceEscalationSteps(ctx context.Context, steps []collections.EscalationStep) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to begin transaction", slog.Any("error", err))
+		return fmt.Errorf("%w: failed to begin transaction: %w", apperrors.ErrDatabase, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, `DELETE FROM escalation_steps`); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to clear escalation steps", slog.Any("error", err))
+		return fmt.Errorf("%w: failed to clear escalation steps: %w", apperrors.ErrDatabase, err)
+	}
+	now := r.clock.Now()
+	for i := range steps {
+		steps[i].CreatedAt = now
+		if _, err := tx.Exec(ctx, `INSERT INTO escalation_steps (days_past_due, action, created_at) VALUES ($1, $2, $3)`,
+			steps[i].DaysPastDue, steps[i].Action, now); err != nil {
+			r.logger.ErrorContext(ctx, "Failed to insert escalation step", slog.Int("daysPastDue", steps[i].DaysPastDue), slog.Any("error", err))
+			return fmt.Errorf("%w: failed to insert escalation step: %w", apperrors.ErrDatabase, err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%w: failed to commit escalation steps: %w", apperrors.ErrDatabase, err)
+	}
+	return nil
+}
+
+func (r *CollectionsRepository) ListEscalations(ctx context.Context) ([]collections.Escalation, error) {
+	query := `
+        SELECT l.id, l.tenant_id, c.id, l.status, l.days_past_due, COALESCE(a.collector, ''), e.days_past_due
+        FROM loans l
+        JOIN customers c ON c.loan_id = l.id
+        LEFT JOIN loan_escalations e ON e.loan_id = l.id
+        LEFT JOIN collection_assignments a ON a.loan_id = l.id AND a.status = 'OPEN'
+        WHERE (l.status != 'PAID_OFF' AND l.days_past_due > 0) OR e.loan_id IS NOT NULL
+        ORDER BY l.id`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query loan escalations", slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to list loan escalations: %w", apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	escalations := []collections.Escalation{}
+	for rows.Next() {
+		var e collections.Escalation
+		if err := rows.Scan(&e.LoanID, &e.TenantID, &e.CustomerID, &e.LoanStatus, &e.DaysPastDue, &e.Collector, &e.LastStep); err != nil {
+			return nil, fmt.Errorf("%w: failed to scan loan escalation: %w", apperrors.ErrDatabase, err)
+		}
+		escalations = append(escalations, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: failed to iterate loan escalations: %w", apperrors.ErrDatabase, err)
+	}
+	return escalations, nil
+}
+
+func (r *CollectionsRepository) SetEscalation(ctx context.Context, loanID int64, step int, at time.Time) error {
+	query := `
+        INSERT INTO loan_escalations (loan_id, days_past_due, escalated_at)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (loan_id) DO UPDATE SET days_past_due = EXCLUDED.days_past_due, escalated_at = EXCLUDED.escalated_at`
+
+	if _, err := r.db.Exec(ctx, query, loanID, step, at); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to store loan escalation", slog.Int64("loanID", loanID), slog.Any("error", err))
+		return fmt.Errorf("%w: failed to store loan escalation: %w", apperrors.ErrDatabase, err)
+	}
+	return nil
+}
+
+func (r *CollectionsRepository) ClearEscalation(ctx context.Context, loanID int64) error {
+	if _, err := r.db.Exec(ctx, `DELETE FROM loan_escalations WHERE loan_id = $1`, loanID); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to clear loan escalation", slog.Int64("loanID", loanID), slog.Any("error", err))
+		return fmt.Errorf("%w: failed to clear loan escalation: %w", apperrors.ErrDatabase, err)
+	}
+	return nil
+}
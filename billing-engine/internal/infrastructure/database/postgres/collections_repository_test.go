@@ -0,0 +1,216 @@
+package postgres
+
+import (
+	"billing-engine/internal/domain/collections"
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupCollectionsRepo(t *testing.T) (context.Context, *CollectionsRepository, pgxmock.PgxPoolIface) {
+	t.Helper()
+	mockPool, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("failed to open a stub database connection: %v", err)
+	}
+	return context.Background(), NewCollectionsRepository(mockPool, testClock, logger), mockPool
+}
+
+var assignmentColumnNames = []string{"id", "loan_id", "customer_id", "collector", "rule_id", "status", "resolution",
+	"assigned_at", "resolved_at", "created_at", "updated_at"}
+
+func TestCollectionsRepositoryCreateAssignment(t *testing.T) {
+	ruleID := int64(7)
+	newAssignment := func() *collections.Assignment {
+		return &collections.Assignment{LoanID: 3, CustomerID: 5, Collector: "alice", RuleID: &ruleID,
+			Status: collections.AssignmentOpen, AssignedAt: testClock.Now()}
+	}
+
+	t.Run("inserts the assignment", func(t *testing.T) {
+		ctx, repo, mockPool := setupCollectionsRepo(t)
+		defer mockPool.Close()
+
+		a := newAssignment()
+		mockPool.ExpectQuery(`INSERT INTO collection_assignments`).
+			WithArgs(int64(3), int64(5), "alice", &ruleID, collections.AssignmentOpen, testClock.Now(), testClock.Now()).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(int64(9), testClock.Now(), testClock.Now()))
+
+		require.NoError(t, repo.CreateAssignment(ctx, a))
+		assert.Equal(t, int64(9), a.ID)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("loan already has an open assignment", func(t *testing.T) {
+		ctx, repo, mockPool := setupCollectionsRepo(t)
+		defer mockPool.Close()
+
+		mockPool.ExpectQuery(`INSERT INTO collection_assignments`).WithArgs(anyArgs(7)...).
+			WillReturnError(&pgconn.PgError{Code: "23505", ConstraintName: "uq_collection_assignments_open_loan"})
+
+		assert.ErrorIs(t, repo.CreateAssignment(ctx, newAssignment()), apperrors.ErrAlreadyExists)
+	})
+}
+
+func TestCollectionsRepositoryListOpenAssignments(t *testing.T) {
+	ctx, repo, mockPool := setupCollectionsRepo(t)
+	defer mockPool.Close()
+
+	columns := append(append([]string{}, assignmentColumnNames...), "customer_name", "loan_status", "days_past_due", "outstanding", "last_action_at")
+	var noRule *int64
+	mockPool.ExpectQuery(`SELECT .+ FROM collection_assignments a\s+JOIN loans l .+ WHERE a.status = 'OPEN'`).WithArgs("alice").
+		WillReturnRows(pgxmock.NewRows(columns).AddRow(int64(9), int64(3), int64(5), "alice", noRule, collections.AssignmentOpen, collections.Resolution(""),
+			testClock.Now(), nil, testClock.Now(), testClock.Now(), "Jane Doe", loan.StatusDelinquent, 45, loan.Money(1_500_000), nil))
+
+	queue, err := repo.ListOpenAssignments(ctx, "alice")
+
+	require.NoError(t, err)
+	require.Len(t, queue, 1)
+	assert.Equal(t, "Jane Doe", queue[0].CustomerName)
+	assert.Equal(t, 45, queue[0].DaysPastDue)
+	assert.Nil(t, queue[0].LastActionAt)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestCollectionsRepositoryGetOpenAssignmentForLoan(t *testing.T) {
+	query := `SELECT .+ FROM collection_assignments a WHERE a.loan_id = \$1 AND a.status = 'OPEN'`
+
+	t.Run("returns the open assignment", func(t *testing.T) {
+		ctx, repo, mockPool := setupCollectionsRepo(t)
+		defer mockPool.Close()
+
+		var noRule *int64
+		mockPool.ExpectQuery(query).WithArgs(int64(3)).
+			WillReturnRows(pgxmock.NewRows(assignmentColumnNames).AddRow(int64(9), int64(3), int64(5), "alice", noRule, collections.AssignmentOpen, collections.Resolution(""),
+				testClock.Now(), nil, testClock.Now(), testClock.Now()))
+
+		a, err := repo.GetOpenAssignmentForLoan(ctx, 3)
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(9), a.ID)
+		assert.Equal(t, "alice", a.Collector)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("loan without an open assignment", func(t *testing.T) {
+		ctx, repo, mockPool := setupCollectionsRepo(t)
+		defer mockPool.Close()
+
+		mockPool.ExpectQuery(query).WithArgs(int64(3)).WillReturnRows(pgxmock.NewRows(assignmentColumnNames))
+
+		_, err := repo.GetOpenAssignmentForLoan(ctx, 3)
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+}
+
+func TestCollectionsRepositoryResolveAssignment(t *testing.T) {
+	ctx, repo, mockPool := setupCollectionsRepo(t)
+	defer mockPool.Close()
+
+	mockPool.ExpectExec(`UPDATE collection_assignments`).
+		WithArgs(collections.AssignmentResolved, collections.ResolutionCured, testClock.Now(), testClock.Now(), int64(9), collections.AssignmentOpen).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	err := repo.ResolveAssignment(ctx, 9, collections.ResolutionCured, testClock.Now())
+
+	assert.ErrorIs(t, err, apperrors.ErrConflict)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestCollectionsRepositoryReassign(t *testing.T) {
+	action := func() *collections.Action {
+		return &collections.Action{AssignmentID: 9, LoanID: 3, Collector: "supervisor", Type: collections.ActionReassigned, Note: "from alice to bob"}
+	}
+
+	t.Run("moves the assignment and records the action", func(t *testing.T) {
+		ctx, repo, mockPool := setupCollectionsRepo(t)
+		defer mockPool.Close()
+
+		mockPool.ExpectBegin()
+		mockPool.ExpectQuery(`SELECT status FROM collection_assignments WHERE id = \$1 FOR UPDATE`).WithArgs(int64(9)).
+			WillReturnRows(pgxmock.NewRows([]string{"status"}).AddRow(collections.AssignmentOpen))
+		mockPool.ExpectExec(`UPDATE collection_assignments SET collector`).WithArgs("bob", testClock.Now(), int64(9)).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mockPool.ExpectQuery(`INSERT INTO collection_actions`).WithArgs(anyArgs(8)...).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "created_at"}).AddRow(int64(12), testClock.Now()))
+		mockPool.ExpectCommit()
+		mockPool.ExpectRollback()
+
+		a := action()
+		require.NoError(t, repo.Reassign(ctx, 9, "bob", a))
+		assert.Equal(t, int64(12), a.ID)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("resolved assignment", func(t *testing.T) {
+		ctx, repo, mockPool := setupCollectionsRepo(t)
+		defer mockPool.Close()
+
+		mockPool.ExpectBegin()
+		mockPool.ExpectQuery(`SELECT status FROM collection_assignments`).WithArgs(int64(9)).
+			WillReturnRows(pgxmock.NewRows([]string{"status"}).AddRow(collections.AssignmentResolved))
+		mockPool.ExpectRollback()
+
+		assert.ErrorIs(t, repo.Reassign(ctx, 9, "bob", action()), apperrors.ErrConflict)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+}
+
+func TestCollectionsRepositoryReplaceEscalationSteps(t *testing.T) {
+	ctx, repo, mockPool := setupCollectionsRepo(t)
+	defer mockPool.Close()
+
+	mockPool.ExpectBegin()
+	mockPool.ExpectExec(`DELETE FROM escalation_steps`).WillReturnResult(pgxmock.NewResult("DELETE", 4))
+	mockPool.ExpectExec(`INSERT INTO escalation_steps`).WithArgs(1, collections.ReminderSMS, testClock.Now()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mockPool.ExpectExec(`INSERT INTO escalation_steps`).WithArgs(10, collections.ReminderCall, testClock.Now()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mockPool.ExpectCommit()
+	mockPool.ExpectRollback()
+
+	steps := []collections.EscalationStep{{DaysPastDue: 1, Action: collections.ReminderSMS}, {DaysPastDue: 10, Action: collections.ReminderCall}}
+	require.NoError(t, repo.ReplaceEscalationSteps(ctx, steps))
+	assert.Equal(t, testClock.Now(), steps[1].CreatedAt)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestCollectionsRepositoryListEscalations(t *testing.T) {
+	ctx, repo, mockPool := setupCollectionsRepo(t)
+	defer mockPool.Close()
+
+	var noStep *int
+	lastStep := 7
+	mockPool.ExpectQuery(`SELECT .+ FROM loans l\s+JOIN customers c .+ LEFT JOIN loan_escalations e`).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "tenant_id", "customer_id", "status", "days_past_due", "collector", "last_step"}).
+			AddRow(int64(3), "default", int64(5), loan.StatusActive, 2, "", noStep).
+			AddRow(int64(4), "acme", int64(6), loan.StatusActive, 0, "alice", &lastStep))
+
+	escalations, err := repo.ListEscalations(ctx)
+
+	require.NoError(t, err)
+	require.Len(t, escalations, 2)
+	assert.Nil(t, escalations[0].LastStep)
+	assert.Equal(t, "acme", escalations[1].TenantID)
+	assert.Equal(t, "alice", escalations[1].Collector)
+	require.NotNil(t, escalations[1].LastStep)
+	assert.Equal(t, 7, *escalations[1].LastStep)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestCollectionsRepositorySetEscalation(t *testing.T) {
+	ctx, repo, mockPool := setupCollectionsRepo(t)
+	defer mockPool.Close()
+
+	mockPool.ExpectExec(`INSERT INTO loan_escalations .+ ON CONFLICT \(loan_id\) DO UPDATE`).WithArgs(int64(3), 14, testClock.Now()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+	require.NoError(t, repo.SetEscalation(ctx, 3, 14, testClock.Now()))
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
@@ -2,15 +2,25 @@ package postgres
 
 import (
 	"billing-engine/internal/config"
+	"billing-engine/internal/pkg/retry"
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 func NewConnectionPool(ctx context.Context, cfg config.DatabaseConfig, logger *slog.Logger) (*pgxpool.Pool, error) {
+	return newConnectionPool(ctx, cfg, nil, logger)
+}
+
+// newConnectionPool runs beforeAcquire, when not nil, on every connection
+// before the pool hands it out.
+func newConnectionPool(ctx context.Context, cfg config.DatabaseConfig, beforeAcquire func(context.Context, *pgx.Conn) bool, logger *slog.Logger) (*pgxpool.Pool, error) {
 	if cfg.URL == "" {
 		return nil, fmt.Errorf("database URL is empty in configuration")
 	}
@@ -19,6 +29,7 @@ func NewConnectionPool(ctx context.Context, cfg config.DatabaseConfig, logger *s
 	if err != nil {
 		return nil, err
 	}
+	poolConfig.BeforeAcquire = beforeAcquire
 
 	logger.Info("Connecting to PostgreSQL database...")
 	dbpool, err := pgxpool.NewWithConfig(ctx, poolConfig)
@@ -52,12 +63,29 @@ func configurePool(cfg config.DatabaseConfig) (*pgxpool.Config, error) {
 	return poolConfig, nil
 }
 
+// pingRetry gives a database that is still starting about ten seconds
+// before startup fails.
+var pingRetry = retry.Policy{MaxAttempts: 5, Initial: 500 * time.Millisecond, Max: 4 * time.Second, Jitter: 0.2, Retryable: pingRetryable}
+
+// pingRetryable is false for an error the server sent back, such as a failed
+// login or an unknown database, which another ping would only repeat.
+func pingRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	return !errors.As(err, &pgErr)
+}
+
 func verifyConnection(ctx context.Context, dbpool *pgxpool.Pool, logger *slog.Logger) error {
 	logger.Info("Pinging database...")
-	pingCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
-	defer cancel()
-
-	if err := dbpool.Ping(pingCtx); err != nil {
+	policy := pingRetry
+	policy.OnRetry = func(attempt int, err error, wait time.Duration) {
+		logger.Warn("Failed to ping database, retrying", slog.Int("attempt", attempt), slog.Duration("wait", wait), slog.Any("error", err))
+	}
+	err := retry.Do(ctx, policy, func(ctx context.Context, _ int) error {
+		pingCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+		return dbpool.Ping(pingCtx)
+	})
+	if err != nil {
 		logger.Error("Failed to ping database", "error", err)
 		return fmt.Errorf("failed to ping database on connect: %w", err)
 	}
@@ -3,11 +3,14 @@ package postgres
 import (
 	"billing-engine/internal/config"
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"os"
 	"testing"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -89,3 +92,8 @@ func TestNewConnectionPool(t *testing.T) {
 		assert.Contains(t, err.Error(), "failed to parse database config from URL")
 	})
 }
+
+func TestPingRetryable(t *testing.T) {
+	assert.True(t, pingRetryable(errors.New("dial tcp 127.0.0.1:5432: connect: connection refused")))
+	assert.False(t, pingRetryable(fmt.Errorf("ping: %w", &pgconn.PgError{Code: "28P01", Message: "password authentication failed"})))
+}
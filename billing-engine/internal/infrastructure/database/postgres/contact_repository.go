@@ -0,0 +1,228 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"billing-engine/internal/domain/contact"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	contactColumns = `id, customer_id, type, value, verified, is_primary, verified_at, created_at, updated_at`
+
+	insertContactQuery = `
+        INSERT INTO customer_contacts (customer_id, type, value, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $4)
+        RETURNING id, created_at, updated_at`
+
+	// demoteContactsQuery clears the primary flag of the customer's other
+	// contacts of a type, before the partial unique index sees two.
+	demoteContactsQuery = `UPDATE customer_contacts SET is_primary = FALSE, updated_at = $1
+        WHERE customer_id = $2 AND type = $3 AND id != $4 AND is_primary`
+	updateContactQuery = `UPDATE customer_contacts SET value = $1, verified = $2, is_primary = $3, verified_at = $4, updated_at = $5
+        WHERE id = $6 AND customer_id = $7
+        RETURNING updated_at`
+
+	saveVerificationQuery = `
+        INSERT INTO customer_contact_verifications (contact_id, value, code_hash, expires_at, attempts, sent_at)
+        VALUES ($1, $2, $3, $4, 0, $5)
+        ON CONFLICT (contact_id) DO UPDATE
+        SET value = EXCLUDED.value, code_hash = EXCLUDED.code_hash, expires_at = EXCLUDED.expires_at, attempts = 0, sent_at = EXCLUDED.sent_at`
+	countFailedAttemptQuery = `UPDATE customer_contact_verifications SET attempts = attempts + 1 WHERE contact_id = $1 RETURNING attempts`
+)
+
+type ContactRepository struct {
+	db     DBPool
+	clock  clock.Clock
+	logger *slog.Logger
+}
+
+var _ contact.Repository = (*ContactRepository)(nil)
+
+// NewContactRepository stores customer contacts and their pending codes; clk
+// stamps created_at and updated_at and nil means the wall clock.
+func NewContactRepository(db DBPool, clk clock.Clock, logger *slog.Logger) *ContactRepository {
+	if db == nil {
+		panic("DBPool cannot be nil for ContactRepository")
+	}
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+		logger.Warn("Warning: No logger provided to NewContactRepository, using default stderr handler")
+	}
+	return &ContactRepository{
+		db:     db,
+		clock:  clock.OrSystem(clk),
+		logger: logger.With("component", "ContactRepository"),
+	}
+}
+
+func scanContact(row pgx.Row, c *contact.Contact) error {
+	return row.Scan(&c.ID, &c.CustomerID, &c.Type, &c.Value, &c.Verified, &c.Primary, &c.VerifiedAt, &c.CreatedAt, &c.UpdatedAt)
+}
+
+// contactError maps the constraint errors a write of c can hit.
+func contactError(err error, c *contact.Contact) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23503":
+			return fmt.Errorf("%w: customer %d", apperrors.ErrNotFound, c.CustomerID)
+		case "23505":
+			return fmt.Errorf("%w: customer %d already has the %s %s", apperrors.ErrConflict, c.CustomerID, c.Type, c.Value)
+		}
+	}
+	return nil
+}
+
+func (r *ContactRepository) Create(ctx context.Context, c *contact.Contact) error {
+	c.Verified, c.Primary, c.VerifiedAt = false, false, nil
+	err := r.db.QueryRow(ctx, insertContactQuery, c.CustomerID, c.Type, c.Value, r.clock.Now()).Scan(&c.ID, &c.CreatedAt, &c.UpdatedAt)
+	if err == nil {
+		return nil
+	}
+	if mapped := contactError(err, c); mapped != nil {
+		return mapped
+	}
+	r.logger.ErrorContext(ctx, "Failed to insert contact", slog.Int64("customerID", c.CustomerID), slog.Any("error", err))
+	return fmt.Errorf("%w: failed to insert contact: %w", apperrors.ErrDatabase, err)
+}
+
+func (r *ContactRepository) List(ctx context.Context, customerID int64) ([]contact.Contact, error) {
+	query := `SELECT ` + contactColumns + ` FROM customer_contacts WHERE customer_id = $1 ORDER BY type, is_primary DESC, id`
+
+	rows, err := r.db.Query(ctx, query, customerID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query contacts", slog.Int64("customerID", customerID), slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to list contacts: %w", apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	contacts := []contact.Contact{}
+	for rows.Next() {
+		var c contact.Contact
+		if err := scanContact(rows, &c); err != nil {
+			return nil, fmt.Errorf("%w: failed to scan contact: %w", apperrors.ErrDatabase, err)
+		}
+		contacts = append(contacts, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: failed to iterate contacts: %w", apperrors.ErrDatabase, err)
+	}
+	return contacts, nil
+}
+
+func (r *ContactRepository) Get(ctx context.Context, customerID, contactID int64) (*contact.Contact, error) {
+	query := `SELECT ` + contactColumns + ` FROM customer_contacts WHERE id = $1 AND customer_id = $2`
+
+	var c contact.Contact
+	if err := scanContact(r.db.QueryRow(ctx, query, contactID, customerID), &c); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%w: contact %d of customer %d", apperrors.ErrNotFound, contactID, customerID)
+		}
+		r.logger.ErrorContext(ctx, "Failed to get contact", slog.Int64("contactID", contactID), slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to get contact: %w", apperrors.ErrDatabase, err)
+	}
+	return &c, nil
+}
+
+func (r *ContactRepository) Update(ctx context.Context, c *contact.Contact) error {
+	logger := r.logger.With(slog.Int64("customerID", c.CustomerID), slog.Int64("contactID", c.ID))
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to begin transaction", slog.Any("error", err))
+		return fmt.Errorf("%w: failed to begin transaction: %w", apperrors.ErrDatabase, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	now := r.clock.Now()
+	if c.Primary {
+		if _, err := tx.Exec(ctx, demoteContactsQuery, now, c.CustomerID, c.Type, c.ID); err != nil {
+			logger.ErrorContext(ctx, "Failed to clear other primary contact", slog.Any("error", err))
+			return fmt.Errorf("%w: failed to clear other primary contact: %w", apperrors.ErrDatabase, err)
+		}
+	}
+	err = tx.QueryRow(ctx, updateContactQuery, c.Value, c.Verified, c.Primary, c.VerifiedAt, now, c.ID, c.CustomerID).Scan(&c.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("%w: contact %d of customer %d", apperrors.ErrNotFound, c.ID, c.CustomerID)
+	}
+	if err != nil {
+		if mapped := contactError(err, c); mapped != nil {
+			return mapped
+		}
+		logger.ErrorContext(ctx, "Failed to update contact", slog.Any("error", err))
+		return fmt.Errorf("%w: failed to update contact: %w", apperrors.ErrDatabase, err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%w: failed to commit contact update: %w", apperrors.ErrDatabase, err)
+	}
+	return nil
+}
+
+func (r *ContactRepository) Delete(ctx context.Context, customerID, contactID int64) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM customer_contacts WHERE id = $1 AND customer_id = $2`, contactID, customerID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to delete contact", slog.Int64("contactID", contactID), slog.Any("error", err))
+		return fmt.Errorf("%w: failed to delete contact: %w", apperrors.ErrDatabase, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: contact %d of customer %d", apperrors.ErrNotFound, contactID, customerID)
+	}
+	return nil
+}
+
+func (r *ContactRepository) SaveVerification(ctx context.Context, v *contact.Verification) error {
+	if _, err := r.db.Exec(ctx, saveVerificationQuery, v.ContactID, v.Value, v.CodeHash, v.ExpiresAt, v.SentAt); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return fmt.Errorf("%w: contact %d", apperrors.ErrNotFound, v.ContactID)
+		}
+		r.logger.ErrorContext(ctx, "Failed to save verification", slog.Int64("contactID", v.ContactID), slog.Any("error", err))
+		return fmt.Errorf("%w: failed to save verification: %w", apperrors.ErrDatabase, err)
+	}
+	v.Attempts = 0
+	return nil
+}
+
+func (r *ContactRepository) GetVerification(ctx context.Context, contactID int64) (*contact.Verification, error) {
+	query := `SELECT contact_id, value, code_hash, expires_at, attempts, sent_at FROM customer_contact_verifications WHERE contact_id = $1`
+
+	var v contact.Verification
+	err := r.db.QueryRow(ctx, query, contactID).Scan(&v.ContactID, &v.Value, &v.CodeHash, &v.ExpiresAt, &v.Attempts, &v.SentAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: no verification pending for contact %d", apperrors.ErrNotFound, contactID)
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to get verification", slog.Int64("contactID", contactID), slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to get verification: %w", apperrors.ErrDatabase, err)
+	}
+	return &v, nil
+}
+
+func (r *ContactRepository) CountFailedAttempt(ctx context.Context, contactID int64) (int, error) {
+	var attempts int
+	err := r.db.QueryRow(ctx, countFailedAttemptQuery, contactID).Scan(&attempts)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, fmt.Errorf("%w: no verification pending for contact %d", apperrors.ErrNotFound, contactID)
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to count verification attempt", slog.Int64("contactID", contactID), slog.Any("error", err))
+		return 0, fmt.Errorf("%w: failed to count verification attempt: %w", apperrors.ErrDatabase, err)
+	}
+	return attempts, nil
+}
+
+func (r *ContactRepository) DeleteVerification(ctx context.Context, contactID int64) error {
+	if _, err := r.db.Exec(ctx, `DELETE FROM customer_contact_verifications WHERE contact_id = $1`, contactID); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to delete verification", slog.Int64("contactID", contactID), slog.Any("error", err))
+		return fmt.Errorf("%w: failed to delete verification: %w", apperrors.ErrDatabase, err)
+	}
+	return nil
+}
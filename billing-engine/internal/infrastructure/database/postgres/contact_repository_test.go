@@ -0,0 +1,138 @@
+package postgres
+
+import (
+	"billing-engine/internal/domain/contact"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupContactRepo(t *testing.T) (context.Context, *ContactRepository, pgxmock.PgxPoolIface) {
+	t.Helper()
+	mockPool, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("failed to open a stub database connection: %v", err)
+	}
+	return context.Background(), NewContactRepository(mockPool, testClock, logger), mockPool
+}
+
+var contactColumnNames = []string{"id", "customer_id", "type", "value", "verified", "is_primary", "verified_at", "created_at", "updated_at"}
+
+func TestContactRepositoryCreate(t *testing.T) {
+	t.Run("stores an unverified contact", func(t *testing.T) {
+		ctx, repo, mockPool := setupContactRepo(t)
+		defer mockPool.Close()
+		now := testClock.Now()
+
+		mockPool.ExpectQuery(regexp.QuoteMeta(insertContactQuery)).WithArgs(int64(7), contact.TypePhone, "+6281234567890", now).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(int64(12), now, now))
+
+		c := &contact.Contact{CustomerID: 7, Type: contact.TypePhone, Value: "+6281234567890", Verified: true}
+		require.NoError(t, repo.Create(ctx, c))
+		assert.Equal(t, int64(12), c.ID)
+		assert.False(t, c.Verified)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	tests := []struct {
+		name    string
+		pgErr   *pgconn.PgError
+		wantErr error
+	}{
+		{"unknown customer", &pgconn.PgError{Code: "23503", ConstraintName: "customer_contacts_customer_id_fkey"}, apperrors.ErrNotFound},
+		{"duplicate value", &pgconn.PgError{Code: "23505", ConstraintName: "uq_customer_contacts_value"}, apperrors.ErrConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, repo, mockPool := setupContactRepo(t)
+			defer mockPool.Close()
+
+			mockPool.ExpectQuery(regexp.QuoteMeta(insertContactQuery)).WithArgs(anyArgs(4)...).WillReturnError(tt.pgErr)
+
+			err := repo.Create(ctx, &contact.Contact{CustomerID: 7, Type: contact.TypeEmail, Value: "ayu@example.com"})
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+		})
+	}
+}
+
+func TestContactRepositoryGet(t *testing.T) {
+	ctx, repo, mockPool := setupContactRepo(t)
+	defer mockPool.Close()
+	now := testClock.Now()
+
+	mockPool.ExpectQuery(`FROM customer_contacts WHERE id = \$1 AND customer_id = \$2`).WithArgs(int64(12), int64(7)).
+		WillReturnRows(pgxmock.NewRows(contactColumnNames).AddRow(int64(12), int64(7), "phone", "+6281234567890", true, true, &now, now, now))
+	mockPool.ExpectQuery(`FROM customer_contacts WHERE id = \$1 AND customer_id = \$2`).WithArgs(int64(99), int64(7)).WillReturnError(pgx.ErrNoRows)
+
+	c, err := repo.Get(ctx, 7, 12)
+	require.NoError(t, err)
+	assert.Equal(t, contact.TypePhone, c.Type)
+	assert.True(t, c.Primary)
+
+	_, err = repo.Get(ctx, 7, 99)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestContactRepositoryUpdate(t *testing.T) {
+	verifiedAt := time.Date(2025, 3, 5, 9, 0, 0, 0, time.UTC)
+
+	t.Run("a primary contact takes the flag from the other", func(t *testing.T) {
+		ctx, repo, mockPool := setupContactRepo(t)
+		defer mockPool.Close()
+		now := testClock.Now()
+		c := &contact.Contact{ID: 13, CustomerID: 7, Type: contact.TypeEmail, Value: "ayu@example.com", Verified: true, Primary: true, VerifiedAt: &verifiedAt}
+
+		mockPool.ExpectBegin()
+		mockPool.ExpectExec(regexp.QuoteMeta(demoteContactsQuery)).WithArgs(now, int64(7), contact.TypeEmail, int64(13)).WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mockPool.ExpectQuery(regexp.QuoteMeta(updateContactQuery)).WithArgs("ayu@example.com", true, true, &verifiedAt, now, int64(13), int64(7)).
+			WillReturnRows(pgxmock.NewRows([]string{"updated_at"}).AddRow(now))
+		mockPool.ExpectCommit()
+		mockPool.ExpectRollback()
+
+		require.NoError(t, repo.Update(ctx, c))
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("unknown contact", func(t *testing.T) {
+		ctx, repo, mockPool := setupContactRepo(t)
+		defer mockPool.Close()
+
+		mockPool.ExpectBegin()
+		mockPool.ExpectQuery(regexp.QuoteMeta(updateContactQuery)).WithArgs(anyArgs(7)...).WillReturnError(pgx.ErrNoRows)
+		mockPool.ExpectRollback()
+
+		err := repo.Update(ctx, &contact.Contact{ID: 99, CustomerID: 7, Type: contact.TypePhone, Value: "+6281234567890"})
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+}
+
+func TestContactRepositoryVerification(t *testing.T) {
+	ctx, repo, mockPool := setupContactRepo(t)
+	defer mockPool.Close()
+	now := testClock.Now()
+	v := &contact.Verification{ContactID: 13, Value: "ayu@example.com", CodeHash: contact.HashCode("482913"), ExpiresAt: now.Add(contact.CodeTTL), SentAt: now}
+
+	mockPool.ExpectExec(regexp.QuoteMeta(saveVerificationQuery)).WithArgs(int64(13), "ayu@example.com", v.CodeHash, v.ExpiresAt, now).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mockPool.ExpectQuery(regexp.QuoteMeta(countFailedAttemptQuery)).WithArgs(int64(13)).WillReturnRows(pgxmock.NewRows([]string{"attempts"}).AddRow(1))
+	mockPool.ExpectQuery(`FROM customer_contact_verifications WHERE contact_id = \$1`).WithArgs(int64(14)).WillReturnError(pgx.ErrNoRows)
+
+	require.NoError(t, repo.SaveVerification(ctx, v))
+	attempts, err := repo.CountFailedAttempt(ctx, 13)
+	require.NoError(t, err)
+	assert.Equal(t, 1, attempts)
+	_, err = repo.GetVerification(ctx, 14)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
@@ -0,0 +1,63 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/scope"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var _ customer.ImportRepository = (*CustomerRepository)(nil)
+
+const insertImportedCustomerQuery = `
+        INSERT INTO customers (name, address, external_ref, is_delinquent, active, created_at, updated_at, tenant_id)
+        VALUES ($1, $2, $3, FALSE, TRUE, $4, $4, $5)
+        ON CONFLICT (external_ref) DO NOTHING
+        RETURNING id`
+
+// InsertImportBatch queues one insert per row and sends them as a single
+// batch. The batch runs in an implicit transaction, so a database error
+// rolls back the whole chunk. The customers belong to ctx's tenant.
+func (r *CustomerRepository) InsertImportBatch(ctx context.Context, rows []customer.ImportRow) ([]int64, error) {
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	r.logger.InfoContext(ctx, "Attempting to insert imported customer batch", slog.Int("rows", len(rows)))
+
+	createdAt := r.clock.Now()
+	tenantID := scope.TenantOrDefault(ctx)
+	batch := &pgx.Batch{}
+	for _, row := range rows {
+		batch.Queue(insertImportedCustomerQuery, row.Name, row.Address, row.ExternalRef, createdAt, tenantID)
+	}
+
+	results := r.db.SendBatch(ctx, batch)
+	ids := make([]int64, len(rows))
+	for i := range rows {
+		err := results.QueryRow().Scan(&ids[i])
+		if errors.Is(err, pgx.ErrNoRows) {
+			ids[i] = 0
+			continue
+		}
+		if err != nil {
+			_ = results.Close()
+			r.logger.ErrorContext(ctx, "Failed to insert imported customer", slog.Int("line", rows[i].Line), slog.Any("error", err))
+			return nil, fmt.Errorf("%w: failed to insert imported customer on line %d: %w", apperrors.ErrDatabase, rows[i].Line, err)
+		}
+	}
+
+	if err := results.Close(); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to close import batch", slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to complete import batch: %w", apperrors.ErrDatabase, err)
+	}
+
+	r.logger.InfoContext(ctx, "Imported customer batch inserted", slog.Int("rows", len(rows)))
+	return ids, nil
+}
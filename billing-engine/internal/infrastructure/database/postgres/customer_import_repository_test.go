@@ -0,0 +1,65 @@
+package postgres
+
+import (
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/scope"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var importRows = []customer.ImportRow{
+	{Line: 2, Name: "Jane", Address: "1 Main St", ExternalRef: "crm-1"},
+	{Line: 3, Name: "Bob", Address: "2 Main St", ExternalRef: "crm-2"},
+}
+
+func TestInsertImportBatchWhenSuccess(t *testing.T) {
+	ctx, repo, mockPool := setupCustomerRepo(t)
+	defer mockPool.Close()
+
+	batch := mockPool.ExpectBatch()
+	batch.ExpectQuery(regexp.QuoteMeta(insertImportedCustomerQuery)).
+		WithArgs("Jane", "1 Main St", "crm-1", testClock.Now(), scope.DefaultTenant).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int64(10)))
+	batch.ExpectQuery(regexp.QuoteMeta(insertImportedCustomerQuery)).
+		WithArgs("Bob", "2 Main St", "crm-2", testClock.Now(), scope.DefaultTenant).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}))
+
+	ids, err := repo.InsertImportBatch(ctx, importRows)
+
+	require.NoError(t, err)
+	assert.Equal(t, []int64{10, 0}, ids, "conflicting external_ref should be reported with a zero ID")
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestInsertImportBatchWhenQueryFails(t *testing.T) {
+	ctx, repo, mockPool := setupCustomerRepo(t)
+	defer mockPool.Close()
+
+	batch := mockPool.ExpectBatch()
+	batch.ExpectQuery(regexp.QuoteMeta(insertImportedCustomerQuery)).
+		WithArgs("Jane", "1 Main St", "crm-1", testClock.Now(), scope.DefaultTenant).
+		WillReturnError(errors.New("deadlock detected"))
+
+	ids, err := repo.InsertImportBatch(ctx, importRows[:1])
+
+	assert.Nil(t, ids)
+	assert.ErrorIs(t, err, apperrors.ErrDatabase)
+	assert.Contains(t, err.Error(), "line 2")
+}
+
+func TestInsertImportBatchWhenEmpty(t *testing.T) {
+	ctx, repo, mockPool := setupCustomerRepo(t)
+	defer mockPool.Close()
+
+	ids, err := repo.InsertImportBatch(ctx, nil)
+
+	assert.NoError(t, err)
+	assert.Empty(t, ids)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
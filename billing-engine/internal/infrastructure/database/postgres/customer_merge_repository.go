@@ -0,0 +1,119 @@
+package postgres
+
+import (
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// lockMergeCustomersQuery locks both rows in ID order, so that two merges
+	// of the same pair cannot deadlock.
+	lockMergeCustomersQuery = `SELECT id, public_id, name, address, is_delinquent, active, loan_id, external_ref, risk_score, risk_grade, risk_scored_at, merged_into_id, merged_at, created_at, updated_at
+        FROM customers WHERE id = ANY($1) ORDER BY id FOR UPDATE`
+	countActiveMandatesQuery = `SELECT COUNT(DISTINCT customer_id) FROM mandates WHERE customer_id = ANY($1) AND status = 'ACTIVE'`
+	// mergeSourceQuery releases the source's loan first, since loan_id is
+	// unique.
+	mergeSourceQuery = `UPDATE customers SET loan_id = NULL, active = FALSE, merged_into_id = $1, merged_at = $2, updated_at = $2 WHERE id = $3`
+	mergeTargetQuery = `UPDATE customers SET loan_id = COALESCE(loan_id, $1), is_delinquent = is_delinquent OR $2, updated_at = $3 WHERE id = $4`
+)
+
+// mergeRelinks are the statements that point the source's records, $2, at
+// the target, $1, each with the count of MergedRecords it fills.
+var mergeRelinks = []struct {
+	query string
+	count func(*customer.MergedRecords) *int64
+}{
+	{`UPDATE notes SET customer_id = $1 WHERE customer_id = $2`, func(m *customer.MergedRecords) *int64 { return &m.Notes }},
+	{`UPDATE attachments SET customer_id = $1 WHERE customer_id = $2`, func(m *customer.MergedRecords) *int64 { return &m.Attachments }},
+	{`UPDATE documents SET customer_id = $1 WHERE customer_id = $2`, func(m *customer.MergedRecords) *int64 { return &m.Documents }},
+	{`UPDATE mandates SET customer_id = $1 WHERE customer_id = $2`, func(m *customer.MergedRecords) *int64 { return &m.Mandates }},
+	{`UPDATE collection_assignments SET customer_id = $1 WHERE customer_id = $2`, func(m *customer.MergedRecords) *int64 { return &m.CollectionAssignments }},
+	{`UPDATE collection_assignments_archive SET customer_id = $1 WHERE customer_id = $2`, func(m *customer.MergedRecords) *int64 { return &m.CollectionAssignments }},
+	{`UPDATE archived_loans SET customer_id = $1 WHERE customer_id = $2`, func(m *customer.MergedRecords) *int64 { return &m.ArchivedLoans }},
+	{`UPDATE event_log SET entity_id = $1 WHERE entity_id = $2`, func(m *customer.MergedRecords) *int64 { return &m.Events }},
+}
+
+func (r *CustomerRepository) MergeCustomers(ctx context.Context, targetID, sourceID int64) (*customer.Merge, error) {
+	logger := r.logger.With(slog.Int64("targetID", targetID), slog.Int64("sourceID", sourceID))
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to begin transaction", slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to begin transaction: %w", apperrors.ErrDatabase, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	target, source, err := r.lockMergeCustomers(ctx, tx, targetID, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	if err := customer.CheckMerge(target, source); err != nil {
+		return nil, err
+	}
+	var withMandate int
+	if err := tx.QueryRow(ctx, countActiveMandatesQuery, []int64{targetID, sourceID}).Scan(&withMandate); err != nil {
+		logger.ErrorContext(ctx, "Failed to count active mandates", slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to count active mandates: %w", apperrors.ErrDatabase, err)
+	}
+	if withMandate > 1 {
+		return nil, fmt.Errorf("%w: customers %d and %d both have an active mandate", apperrors.ErrConflict, targetID, sourceID)
+	}
+
+	now := r.clock.Now()
+	if _, err := tx.Exec(ctx, mergeSourceQuery, targetID, now, sourceID); err != nil {
+		logger.ErrorContext(ctx, "Failed to mark customer merged", slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to mark customer merged: %w", apperrors.ErrDatabase, err)
+	}
+	if _, err := tx.Exec(ctx, mergeTargetQuery, source.LoanID, source.IsDelinquent, now, targetID); err != nil {
+		logger.ErrorContext(ctx, "Failed to move loan to merge target", slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to move loan to merge target: %w", apperrors.ErrDatabase, err)
+	}
+	merge := customer.NewMerge(target, source, now)
+	for _, relink := range mergeRelinks {
+		tag, err := tx.Exec(ctx, relink.query, targetID, sourceID)
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to re-link merged records", slog.String("query", relink.query), slog.Any("error", err))
+			return nil, fmt.Errorf("%w: failed to re-link merged records: %w", apperrors.ErrDatabase, err)
+		}
+		*relink.count(&merge.Moved) += tag.RowsAffected()
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("%w: failed to commit customer merge: %w", apperrors.ErrDatabase, err)
+	}
+
+	logger.InfoContext(ctx, "Customers merged successfully")
+	return merge, nil
+}
+
+func (r *CustomerRepository) lockMergeCustomers(ctx context.Context, tx pgx.Tx, targetID, sourceID int64) (*customer.Customer, *customer.Customer, error) {
+	rows, err := tx.Query(ctx, lockMergeCustomersQuery, []int64{targetID, sourceID})
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to lock customers for merge", slog.Any("error", err))
+		return nil, nil, fmt.Errorf("%w: failed to lock customers for merge: %w", apperrors.ErrDatabase, err)
+	}
+	customers, err := r.scanCustomers(ctx, rows)
+	rows.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+	var target, source *customer.Customer
+	for _, c := range customers {
+		switch c.CustomerID {
+		case targetID:
+			target = c
+		case sourceID:
+			source = c
+		}
+	}
+	switch {
+	case target == nil:
+		return nil, nil, fmt.Errorf("%w: customer %d", apperrors.ErrNotFound, targetID)
+	case source == nil:
+		return nil, nil, fmt.Errorf("%w: customer %d", apperrors.ErrNotFound, sourceID)
+	}
+	return target, source, nil
+}
@@ -0,0 +1,107 @@
+package postgres
+
+import (
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/pkg/apperrors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mergeCustomerRows(customers ...customer.Customer) *pgxmock.Rows {
+	rows := pgxmock.NewRows([]string{"id", "public_id", "name", "address", "is_delinquent", "active", "loan_id", "external_ref", "risk_score", "risk_grade", "risk_scored_at", "merged_into_id", "merged_at", "created_at", "updated_at"})
+	for _, c := range customers {
+		rows.AddRow(c.CustomerID, c.PublicID, c.Name, c.Address, c.IsDelinquent, c.Active, c.LoanID, c.ExternalRef, c.RiskScore, c.RiskGrade, c.RiskScoredAt, c.MergedIntoID, c.MergedAt, c.CreateDate, c.UpdatedAt)
+	}
+	return rows
+}
+
+func TestMergeCustomers(t *testing.T) {
+	sourceLoan := int64(77)
+	target := customer.Customer{CustomerID: 10, Name: "Budi Santoso", Active: true}
+	source := customer.Customer{CustomerID: 11, Name: "Budi Santoso", Active: true, IsDelinquent: true, LoanID: &sourceLoan}
+	ids := []int64{10, 11}
+
+	t.Run("moves the source's loan and records in one transaction", func(t *testing.T) {
+		ctx, repo, mockPool := setupCustomerRepo(t)
+		defer mockPool.Close()
+		now := testClock.Now()
+
+		mockPool.ExpectBegin()
+		mockPool.ExpectQuery(regexp.QuoteMeta(lockMergeCustomersQuery)).WithArgs(ids).WillReturnRows(mergeCustomerRows(target, source))
+		mockPool.ExpectQuery(regexp.QuoteMeta(countActiveMandatesQuery)).WithArgs(ids).WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(1))
+		mockPool.ExpectExec(regexp.QuoteMeta(mergeSourceQuery)).WithArgs(int64(10), now, int64(11)).WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mockPool.ExpectExec(regexp.QuoteMeta(mergeTargetQuery)).WithArgs(&sourceLoan, true, now, int64(10)).WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		for i, relink := range mergeRelinks {
+			mockPool.ExpectExec(regexp.QuoteMeta(relink.query)).WithArgs(int64(10), int64(11)).WillReturnResult(pgxmock.NewResult("UPDATE", int64(i+1)))
+		}
+		mockPool.ExpectCommit()
+		mockPool.ExpectRollback()
+
+		merge, err := repo.MergeCustomers(ctx, 10, 11)
+
+		require.NoError(t, err)
+		assert.Equal(t, &sourceLoan, merge.LoanID)
+		assert.Equal(t, &sourceLoan, merge.Target.LoanID)
+		assert.True(t, merge.Target.IsDelinquent)
+		assert.Nil(t, merge.Source.LoanID)
+		assert.False(t, merge.Source.Active)
+		assert.Equal(t, int64(10), *merge.Source.MergedIntoID)
+		assert.Equal(t, now, *merge.Source.MergedAt)
+		assert.Equal(t, customer.MergedRecords{Notes: 1, Attachments: 2, Documents: 3, Mandates: 4, CollectionAssignments: 5 + 6, ArchivedLoans: 7, Events: 8}, merge.Moved,
+			"live and archived collection assignments are counted together")
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("unknown customer", func(t *testing.T) {
+		ctx, repo, mockPool := setupCustomerRepo(t)
+		defer mockPool.Close()
+
+		mockPool.ExpectBegin()
+		mockPool.ExpectQuery(regexp.QuoteMeta(lockMergeCustomersQuery)).WithArgs(ids).WillReturnRows(mergeCustomerRows(target))
+		mockPool.ExpectRollback()
+
+		_, err := repo.MergeCustomers(ctx, 10, 11)
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+		assert.ErrorContains(t, err, "customer 11")
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("refused merge writes nothing", func(t *testing.T) {
+		ctx, repo, mockPool := setupCustomerRepo(t)
+		defer mockPool.Close()
+		mergedInto, mergedAt := int64(3), time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+		merged := source
+		merged.MergedIntoID, merged.MergedAt = &mergedInto, &mergedAt
+
+		mockPool.ExpectBegin()
+		mockPool.ExpectQuery(regexp.QuoteMeta(lockMergeCustomersQuery)).WithArgs(ids).WillReturnRows(mergeCustomerRows(target, merged))
+		mockPool.ExpectRollback()
+
+		_, err := repo.MergeCustomers(ctx, 10, 11)
+
+		assert.ErrorIs(t, err, customer.ErrAlreadyMerged)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("both with an active mandate", func(t *testing.T) {
+		ctx, repo, mockPool := setupCustomerRepo(t)
+		defer mockPool.Close()
+
+		mockPool.ExpectBegin()
+		mockPool.ExpectQuery(regexp.QuoteMeta(lockMergeCustomersQuery)).WithArgs(ids).WillReturnRows(mergeCustomerRows(target, source))
+		mockPool.ExpectQuery(regexp.QuoteMeta(countActiveMandatesQuery)).WithArgs(ids).WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(2))
+		mockPool.ExpectRollback()
+
+		_, err := repo.MergeCustomers(ctx, 10, 11)
+
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+		assert.ErrorContains(t, err, "active mandate")
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+}
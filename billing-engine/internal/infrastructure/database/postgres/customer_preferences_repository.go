@@ -0,0 +1,67 @@
+package postgres
+
+import (
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	// getPreferencesQuery joins from customers so that a customer without
+	// stored preferences still answers, with the column defaults.
+	getPreferencesQuery = `
+        SELECT c.id, COALESCE(p.preferred_channel, ''), COALESCE(p.language, ''),
+               COALESCE(p.marketing_opt_out, FALSE), COALESCE(p.transactional_opt_out, FALSE), p.updated_at
+        FROM customers c LEFT JOIN customer_preferences p ON p.customer_id = c.id
+        WHERE c.id = $1`
+	savePreferencesQuery = `
+        INSERT INTO customer_preferences (customer_id, preferred_channel, language, marketing_opt_out, transactional_opt_out, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        ON CONFLICT (customer_id) DO UPDATE SET
+            preferred_channel = EXCLUDED.preferred_channel,
+            language = EXCLUDED.language,
+            marketing_opt_out = EXCLUDED.marketing_opt_out,
+            transactional_opt_out = EXCLUDED.transactional_opt_out,
+            updated_at = EXCLUDED.updated_at`
+)
+
+func (r *CustomerRepository) GetPreferences(ctx context.Context, customerID int64) (*customer.Preferences, error) {
+	var p customer.Preferences
+	var updatedAt *time.Time
+	err := r.db.QueryRow(ctx, getPreferencesQuery, customerID).Scan(
+		&p.CustomerID, &p.PreferredChannel, &p.Language, &p.MarketingOptOut, &p.TransactionalOptOut, &updatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%w: customer %d", apperrors.ErrNotFound, customerID)
+		}
+		r.logger.ErrorContext(ctx, "Failed to get customer preferences", slog.Int64("customerID", customerID), slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to get customer preferences: %w", apperrors.ErrDatabase, err)
+	}
+	if updatedAt != nil {
+		p.UpdatedAt = *updatedAt
+	}
+	return &p, nil
+}
+
+func (r *CustomerRepository) SavePreferences(ctx context.Context, p *customer.Preferences) error {
+	updatedAt := r.clock.Now()
+	_, err := r.db.Exec(ctx, savePreferencesQuery,
+		p.CustomerID, p.PreferredChannel, p.Language, p.MarketingOptOut, p.TransactionalOptOut, updatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return fmt.Errorf("%w: customer %d", apperrors.ErrNotFound, p.CustomerID)
+		}
+		r.logger.ErrorContext(ctx, "Failed to save customer preferences", slog.Int64("customerID", p.CustomerID), slog.Any("error", err))
+		return fmt.Errorf("%w: failed to save customer preferences: %w", apperrors.ErrDatabase, err)
+	}
+	p.UpdatedAt = updatedAt
+	return nil
+}
@@ -0,0 +1,89 @@
+package postgres
+
+import (
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/pkg/apperrors"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetPreferences(t *testing.T) {
+	columns := []string{"id", "preferred_channel", "language", "marketing_opt_out", "transactional_opt_out", "updated_at"}
+
+	t.Run("stored preferences", func(t *testing.T) {
+		ctx, repo, mockPool := setupCustomerRepo(t)
+		defer mockPool.Close()
+		updatedAt := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+		mockPool.ExpectQuery(regexp.QuoteMeta(getPreferencesQuery)).WithArgs(int64(1)).
+			WillReturnRows(pgxmock.NewRows(columns).AddRow(int64(1), "sms", "id", true, false, &updatedAt))
+
+		prefs, err := repo.GetPreferences(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, &customer.Preferences{CustomerID: 1, PreferredChannel: "sms", Language: "id", MarketingOptOut: true, UpdatedAt: updatedAt}, prefs)
+		assert.NoError(t, mockPool.ExpectationsWereMet())
+	})
+
+	t.Run("never set", func(t *testing.T) {
+		ctx, repo, mockPool := setupCustomerRepo(t)
+		defer mockPool.Close()
+		mockPool.ExpectQuery(regexp.QuoteMeta(getPreferencesQuery)).WithArgs(int64(2)).
+			WillReturnRows(pgxmock.NewRows(columns).AddRow(int64(2), "", "", false, false, (*time.Time)(nil)))
+
+		prefs, err := repo.GetPreferences(ctx, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, &customer.Preferences{CustomerID: 2}, prefs)
+	})
+
+	t.Run("unknown customer", func(t *testing.T) {
+		ctx, repo, mockPool := setupCustomerRepo(t)
+		defer mockPool.Close()
+		mockPool.ExpectQuery(regexp.QuoteMeta(getPreferencesQuery)).WithArgs(int64(3)).WillReturnError(pgx.ErrNoRows)
+
+		_, err := repo.GetPreferences(ctx, 3)
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+}
+
+func TestSavePreferences(t *testing.T) {
+	t.Run("upserts and stamps updated_at", func(t *testing.T) {
+		ctx, repo, mockPool := setupCustomerRepo(t)
+		defer mockPool.Close()
+		mockPool.ExpectExec(regexp.QuoteMeta(savePreferencesQuery)).
+			WithArgs(int64(1), "email", "en-US", false, true, testClock.Now()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+		prefs := &customer.Preferences{CustomerID: 1, PreferredChannel: "email", Language: "en-US", TransactionalOptOut: true}
+		assert.NoError(t, repo.SavePreferences(ctx, prefs))
+		assert.True(t, prefs.UpdatedAt.Equal(testClock.Now()))
+		assert.NoError(t, mockPool.ExpectationsWereMet())
+	})
+
+	t.Run("unknown customer", func(t *testing.T) {
+		ctx, repo, mockPool := setupCustomerRepo(t)
+		defer mockPool.Close()
+		mockPool.ExpectExec(regexp.QuoteMeta(savePreferencesQuery)).
+			WithArgs(int64(9), "", "", false, false, testClock.Now()).
+			WillReturnError(&pgconn.PgError{Code: "23503"})
+
+		err := repo.SavePreferences(ctx, &customer.Preferences{CustomerID: 9})
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		ctx, repo, mockPool := setupCustomerRepo(t)
+		defer mockPool.Close()
+		mockPool.ExpectExec(regexp.QuoteMeta(savePreferencesQuery)).
+			WithArgs(int64(1), "", "", false, false, testClock.Now()).
+			WillReturnError(errors.New("connection reset"))
+
+		err := repo.SavePreferences(ctx, &customer.Preferences{CustomerID: 1})
+		assert.ErrorIs(t, err, apperrors.ErrDatabase)
+	})
+}
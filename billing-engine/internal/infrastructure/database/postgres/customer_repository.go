@@ -8,19 +8,26 @@ import (
 	"os"
 
 	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/infrastructure/database/sqlbuilder"
 	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"billing-engine/internal/pkg/scope"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 )
 
 type CustomerRepository struct {
 	db     DBPool
+	clock  clock.Clock
 	logger *slog.Logger
 }
 
 var _ customer.CustomerRepository = (*CustomerRepository)(nil)
 
-func NewCustomerRepository(db DBPool, logger *slog.Logger) *CustomerRepository {
+// NewCustomerRepository builds the customer repository. created_at and
+// updated_at are written from clk; nil means the wall clock.
+func NewCustomerRepository(db DBPool, clk clock.Clock, logger *slog.Logger) *CustomerRepository {
 	if db == nil {
 		panic("DBPool cannot be nil for CustomerRepository")
 	}
@@ -31,52 +38,11 @@ func NewCustomerRepository(db DBPool, logger *slog.Logger) *CustomerRepository {
 	}
 	return &CustomerRepository{
 		db:     db,
+		clock:  clock.OrSystem(clk),
 		logger: logger.With("component", "CustomerRepository"),
 	}
 }
 
-func (r *CustomerRepository) BeginTx(ctx context.Context) (pgx.Tx, error) {
-
-	r.logger.InfoContext(ctx, "Beginning transaction")
-	tx, err := r.db.Begin(ctx)
-	if err != nil {
-		r.logger.ErrorContext(ctx, "Failed to begin transaction", slog.Any("error", err))
-
-		return nil, fmt.Errorf("%w: failed to begin transaction: %w", apperrors.ErrDatabase, err)
-	}
-	return tx, nil
-}
-
-func (r *CustomerRepository) CommitTx(ctx context.Context, tx pgx.Tx) error {
-
-	r.logger.InfoContext(ctx, "Committing transaction")
-	err := tx.Commit(ctx)
-	if err != nil {
-
-		r.logger.ErrorContext(ctx, "Failed to commit transaction", slog.Any("error", err))
-		return fmt.Errorf("%w: failed to commit transaction: %w", apperrors.ErrDatabase, err)
-	}
-	r.logger.InfoContext(ctx, "Transaction committed successfully")
-	return nil
-}
-
-func (r *CustomerRepository) RollbackTx(ctx context.Context, tx pgx.Tx) error {
-
-	r.logger.InfoContext(ctx, "Rolling back transaction")
-	err := tx.Rollback(ctx)
-
-	if err != nil && !errors.Is(err, pgx.ErrTxClosed) {
-		r.logger.ErrorContext(ctx, "Failed to rollback transaction", slog.Any("error", err))
-		return fmt.Errorf("%w: failed to rollback transaction: %w", apperrors.ErrDatabase, err)
-	}
-	if err == nil {
-		r.logger.InfoContext(ctx, "Transaction rolled back successfully")
-	} else {
-		r.logger.InfoContext(ctx, "Transaction rollback attempted on closed transaction")
-	}
-	return nil
-}
-
 func (r *CustomerRepository) Save(ctx context.Context, cust *customer.Customer) error {
 	if cust == nil {
 		return fmt.Errorf("%w: customer cannot be nil", apperrors.ErrInvalidArgument)
@@ -96,16 +62,24 @@ func (r *CustomerRepository) createCustomer(ctx context.Context, cust *customer.
 	r.logger.InfoContext(ctx, "Attempting to insert new customer", slog.String("name", cust.Name))
 
 	query := `
-        INSERT INTO customers (name, address, is_delinquent, active, loan_id, created_at, updated_at)
-        VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+        INSERT INTO customers (public_id, name, address, is_delinquent, active, loan_id, external_ref, created_at, updated_at, tenant_id)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8, $9)
         RETURNING id, created_at, updated_at`
 
+	if cust.PublicID == uuid.Nil {
+		cust.PublicID = uuid.New()
+	}
+
 	err := r.db.QueryRow(ctx, query,
+		cust.PublicID,
 		cust.Name,
 		cust.Address,
 		cust.IsDelinquent,
 		cust.Active,
 		cust.LoanID,
+		cust.ExternalRef,
+		r.clock.Now(),
+		scope.TenantOrDefault(ctx),
 	).Scan(
 		&cust.CustomerID,
 		&cust.CreateDate,
@@ -140,17 +114,20 @@ func (r *CustomerRepository) updateCustomer(ctx context.Context, cust *customer.
             is_delinquent = $3,
             active = $4,
             loan_id = $5,
-            updated_at = NOW()
-        WHERE id = $6`
+            updated_at = $6
+        WHERE id = $7`
 
-	cmdTag, err := r.db.Exec(ctx, query,
+	updatedAt := r.clock.Now()
+	query, args := sqlbuilder.ScopeToTenant(ctx, query, "tenant_id",
 		cust.Name,
 		cust.Address,
 		cust.IsDelinquent,
 		cust.Active,
 		cust.LoanID,
+		updatedAt,
 		cust.CustomerID,
 	)
+	cmdTag, err := r.db.Exec(ctx, query, args...)
 
 	if err != nil {
 
@@ -169,6 +146,7 @@ func (r *CustomerRepository) updateCustomer(ctx context.Context, cust *customer.
 		return apperrors.ErrNotFound
 	}
 
+	cust.UpdatedAt = updatedAt
 	r.logger.InfoContext(ctx, "Customer updated successfully")
 
 	return nil
@@ -179,18 +157,26 @@ func (r *CustomerRepository) FindByID(ctx context.Context, customerID int64) (*c
 	r.logger.InfoContext(ctx, "Attempting to find customer by ID")
 
 	query := `
-        SELECT id, name, address, is_delinquent, active, loan_id, created_at, updated_at
+        SELECT id, public_id, name, address, is_delinquent, active, loan_id, external_ref, risk_score, risk_grade, risk_scored_at, merged_into_id, merged_at, created_at, updated_at
         FROM customers
         WHERE id = $1`
+	query, args := sqlbuilder.ScopeToTenant(ctx, query, "tenant_id", customerID)
 
 	var cust customer.Customer
-	err := r.db.QueryRow(ctx, query, customerID).Scan(
+	err := r.db.QueryRow(ctx, query, args...).Scan(
 		&cust.CustomerID,
+		&cust.PublicID,
 		&cust.Name,
 		&cust.Address,
 		&cust.IsDelinquent,
 		&cust.Active,
 		&cust.LoanID,
+		&cust.ExternalRef,
+		&cust.RiskScore,
+		&cust.RiskGrade,
+		&cust.RiskScoredAt,
+		&cust.MergedIntoID,
+		&cust.MergedAt,
 		&cust.CreateDate,
 		&cust.UpdatedAt,
 	)
@@ -213,18 +199,26 @@ func (r *CustomerRepository) FindByLoanID(ctx context.Context, loanID int64) (*c
 	r.logger.InfoContext(ctx, "Attempting to find customer by loan ID")
 
 	query := `
-        SELECT id, name, address, is_delinquent, active, loan_id, created_at, updated_at
+        SELECT id, public_id, name, address, is_delinquent, active, loan_id, external_ref, risk_score, risk_grade, risk_scored_at, merged_into_id, merged_at, created_at, updated_at
         FROM customers
         WHERE loan_id = $1`
+	query, args := sqlbuilder.ScopeToTenant(ctx, query, "tenant_id", &loanID)
 
 	var cust customer.Customer
-	err := r.db.QueryRow(ctx, query, &loanID).Scan(
+	err := r.db.QueryRow(ctx, query, args...).Scan(
 		&cust.CustomerID,
+		&cust.PublicID,
 		&cust.Name,
 		&cust.Address,
 		&cust.IsDelinquent,
 		&cust.Active,
 		&cust.LoanID,
+		&cust.ExternalRef,
+		&cust.RiskScore,
+		&cust.RiskGrade,
+		&cust.RiskScoredAt,
+		&cust.MergedIntoID,
+		&cust.MergedAt,
 		&cust.CreateDate,
 		&cust.UpdatedAt,
 	)
@@ -242,20 +236,114 @@ func (r *CustomerRepository) FindByLoanID(ctx context.Context, loanID int64) (*c
 	return &cust, nil
 }
 
-func (r *CustomerRepository) FindAll(ctx context.Context, activeOnly bool) ([]*customer.Customer, error) {
+func (r *CustomerRepository) FindByExternalRef(ctx context.Context, externalRef string) (*customer.Customer, error) {
+
+	r.logger.InfoContext(ctx, "Attempting to find customer by external reference")
+
+	query := `
+        SELECT id, public_id, name, address, is_delinquent, active, loan_id, external_ref, risk_score, risk_grade, risk_scored_at, merged_into_id, merged_at, created_at, updated_at
+        FROM customers
+        WHERE external_ref = $1`
+	query, args := sqlbuilder.ScopeToTenant(ctx, query, "tenant_id", externalRef)
+
+	var cust customer.Customer
+	err := r.db.QueryRow(ctx, query, args...).Scan(
+		&cust.CustomerID,
+		&cust.PublicID,
+		&cust.Name,
+		&cust.Address,
+		&cust.IsDelinquent,
+		&cust.Active,
+		&cust.LoanID,
+		&cust.ExternalRef,
+		&cust.RiskScore,
+		&cust.RiskGrade,
+		&cust.RiskScoredAt,
+		&cust.MergedIntoID,
+		&cust.MergedAt,
+		&cust.CreateDate,
+		&cust.UpdatedAt,
+	)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.logger.WarnContext(ctx, "Customer not found for the given external reference")
+			return nil, apperrors.ErrNotFound
+		}
+		r.logger.ErrorContext(ctx, "Failed to query/scan customer by external reference", slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to get customer by external reference: %w", apperrors.ErrDatabase, err)
+	}
+
+	r.logger.InfoContext(ctx, "Customer found successfully by external reference", slog.Int64("customerID", cust.CustomerID))
+	return &cust, nil
+}
+
+func (r *CustomerRepository) FindByPublicID(ctx context.Context, publicID uuid.UUID) (*customer.Customer, error) {
+
+	r.logger.InfoContext(ctx, "Attempting to find customer by public ID")
+
+	query := `
+        SELECT id, public_id, name, address, is_delinquent, active, loan_id, external_ref, risk_score, risk_grade, risk_scored_at, merged_into_id, merged_at, created_at, updated_at
+        FROM customers
+        WHERE public_id = $1`
+	query, args := sqlbuilder.ScopeToTenant(ctx, query, "tenant_id", publicID)
+
+	var cust customer.Customer
+	err := r.db.QueryRow(ctx, query, args...).Scan(
+		&cust.CustomerID,
+		&cust.PublicID,
+		&cust.Name,
+		&cust.Address,
+		&cust.IsDelinquent,
+		&cust.Active,
+		&cust.LoanID,
+		&cust.ExternalRef,
+		&cust.RiskScore,
+		&cust.RiskGrade,
+		&cust.RiskScoredAt,
+		&cust.MergedIntoID,
+		&cust.MergedAt,
+		&cust.CreateDate,
+		&cust.UpdatedAt,
+	)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.logger.WarnContext(ctx, "Customer not found for the given public ID")
+			return nil, apperrors.ErrNotFound
+		}
+		r.logger.ErrorContext(ctx, "Failed to query/scan customer by public ID", slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to get customer by public ID: %w", apperrors.ErrDatabase, err)
+	}
+
+	r.logger.InfoContext(ctx, "Customer found successfully by public ID", slog.Int64("customerID", cust.CustomerID))
+	return &cust, nil
+}
+
+const findAllCustomersQuery = `SELECT id, public_id, name, address, is_delinquent, active, loan_id, external_ref, risk_score, risk_grade, risk_scored_at, merged_into_id, merged_at, created_at, updated_at FROM customers`
+
+// customerSortColumns maps the sort fields of customer.ListFilter to
+// columns.
+var customerSortColumns = map[string]string{"id": "id", "name": "name", "created_at": "created_at", "updated_at": "updated_at"}
+
+func (r *CustomerRepository) FindAll(ctx context.Context, filter customer.ListFilter) ([]*customer.Customer, error) {
 
 	r.logger.InfoContext(ctx, "Attempting to find all customers")
 
-	baseQuery := `
-        SELECT id, name, address, is_delinquent, active, loan_id, created_at, updated_at
-        FROM customers`
-	args := []any{}
-	query := baseQuery
-	if activeOnly {
-		query += " WHERE active = $1"
-		args = append(args, true)
+	q := sqlbuilder.Select(findAllCustomersQuery).WhereTenant(ctx, "tenant_id")
+	if filter.Active != nil {
+		q.Where("active = ?", *filter.Active)
+	}
+	if filter.Delinquent != nil {
+		q.Where("is_delinquent = ?", *filter.Delinquent)
+	}
+	if filter.AfterID > 0 {
+		q.Where("id > ?", filter.AfterID)
+	}
+	query, args, err := q.OrderBy(filter.Sort, customerSortColumns, "id").Page(filter.Limit, filter.Offset).Build()
+	if err != nil {
+		return nil, err
 	}
-	query += " ORDER BY id ASC"
 
 	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
@@ -265,43 +353,43 @@ func (r *CustomerRepository) FindAll(ctx context.Context, activeOnly bool) ([]*c
 	}
 	defer rows.Close()
 
-	customers := make([]*customer.Customer, 0)
-	for rows.Next() {
-		var cust customer.Customer
-		err := rows.Scan(
-			&cust.CustomerID,
-			&cust.Name,
-			&cust.Address,
-			&cust.IsDelinquent,
-			&cust.Active,
-			&cust.LoanID,
-			&cust.CreateDate,
-			&cust.UpdatedAt,
-		)
-		if err != nil {
-			r.logger.ErrorContext(ctx, "Failed to scan customer row", slog.Any("error", err))
-
-			return nil, fmt.Errorf("%w: failed to scan customer row: %w", apperrors.ErrDatabase, err)
-		}
-		customers = append(customers, &cust)
-	}
-
-	if err = rows.Err(); err != nil {
-		r.logger.ErrorContext(ctx, "Error iterating customer rows", slog.Any("error", err))
-		return nil, fmt.Errorf("%w: error iterating customer rows: %w", apperrors.ErrDatabase, err)
+	customers, err := r.scanCustomers(ctx, rows)
+	if err != nil {
+		return nil, err
 	}
 
 	r.logger.InfoContext(ctx, "Finished finding customers", slog.Int("count", len(customers)))
 	return customers, nil
 }
 
+func (r *CustomerRepository) FindByNameContaining(ctx context.Context, part string, limit int) ([]*customer.Customer, error) {
+	query, args, err := sqlbuilder.Select(findAllCustomersQuery).
+		WhereTenant(ctx, "tenant_id").
+		Where("active = ?", true).
+		Where(`lower(name) LIKE lower(?) ESCAPE '\'`, sqlbuilder.Contains(part)).
+		OrderBy("", nil, "id").
+		Page(limit, 0).
+		Build()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query customers by name", slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to query customers by name: %w", apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+	return r.scanCustomers(ctx, rows)
+}
+
 func (r *CustomerRepository) Delete(ctx context.Context, customerID int64) error {
 
 	r.logger.InfoContext(ctx, "Attempting to delete customer")
 
-	query := `DELETE FROM customers WHERE id = $1`
+	query, args := sqlbuilder.ScopeToTenant(ctx, `DELETE FROM customers WHERE id = $1`, "tenant_id", customerID)
 
-	cmdTag, err := r.db.Exec(ctx, query, customerID)
+	cmdTag, err := r.db.Exec(ctx, query, args...)
 	if err != nil {
 
 		r.logger.ErrorContext(ctx, "Failed to execute delete customer", slog.Any("error", err))
@@ -320,9 +408,10 @@ func (r *CustomerRepository) Delete(ctx context.Context, customerID int64) error
 func (r *CustomerRepository) SetDelinquencyStatus(ctx context.Context, customerID int64, isDelinquent bool) error {
 	r.logger.InfoContext(ctx, "Attempting to set delinquency status")
 
-	query := `UPDATE customers SET is_delinquent = $1, updated_at = NOW() WHERE id = $2`
+	query, args := sqlbuilder.ScopeToTenant(ctx, `UPDATE customers SET is_delinquent = $1, updated_at = $2 WHERE id = $3`, "tenant_id",
+		isDelinquent, r.clock.Now(), customerID)
 
-	cmdTag, err := r.db.Exec(ctx, query, isDelinquent, customerID)
+	cmdTag, err := r.db.Exec(ctx, query, args...)
 	if err != nil {
 		r.logger.ErrorContext(ctx, "Failed to execute update delinquency status", slog.Any("error", err))
 		return fmt.Errorf("%w: failed to update delinquency status: %w", apperrors.ErrDatabase, err)
@@ -337,13 +426,114 @@ func (r *CustomerRepository) SetDelinquencyStatus(ctx context.Context, customerI
 	return nil
 }
 
+func (r *CustomerRepository) SetRiskScore(ctx context.Context, a *customer.RiskAssessment) error {
+	r.logger.InfoContext(ctx, "Attempting to set risk score")
+
+	query, args := sqlbuilder.ScopeToTenant(ctx, `UPDATE customers SET risk_score = $1, risk_grade = $2, risk_scored_at = $3, updated_at = $4 WHERE id = $5`, "tenant_id",
+		a.Score, a.Grade, a.ScoredAt, r.clock.Now(), a.CustomerID)
+
+	cmdTag, err := r.db.Exec(ctx, query, args...)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to execute update risk score", slog.Any("error", err))
+		return fmt.Errorf("%w: failed to update risk score: %w", apperrors.ErrDatabase, err)
+	}
+
+	if cmdTag.RowsAffected() == 0 {
+		r.logger.WarnContext(ctx, "Update risk score affected zero rows, customer likely not found")
+		return apperrors.ErrNotFound
+	}
+
+	r.logger.InfoContext(ctx, "Customer risk score updated successfully")
+	return nil
+}
+
+// setDelinquencyStatusBulkQuery takes the IDs and flags as two parallel
+// arrays, so the statement and its parameter count stay the same however many
+// customers change.
+const setDelinquencyStatusBulkQuery = `
+        UPDATE customers AS c SET is_delinquent = v.is_delinquent, updated_at = $3
+        FROM unnest($1::bigint[], $2::boolean[]) AS v(id, is_delinquent)
+        WHERE c.id = v.id AND c.is_delinquent <> v.is_delinquent
+        RETURNING c.id, c.public_id, c.name, c.address, c.is_delinquent, c.active, c.loan_id, c.external_ref, c.risk_score, c.risk_grade, c.risk_scored_at, c.merged_into_id, c.merged_at, c.created_at, c.updated_at`
+
+func (r *CustomerRepository) SetDelinquencyStatusBulk(ctx context.Context, updates []customer.CustomerDelinquency) ([]*customer.Customer, error) {
+	r.logger.InfoContext(ctx, "Attempting to set delinquency status in bulk", slog.Int("count", len(updates)))
+
+	ids := make([]int64, len(updates))
+	flags := make([]bool, len(updates))
+	for i, u := range updates {
+		ids[i], flags[i] = u.CustomerID, u.IsDelinquent
+	}
+
+	rows, err := r.db.Query(ctx, setDelinquencyStatusBulkQuery, ids, flags, r.clock.Now())
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to execute bulk update delinquency status", slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to update delinquency statuses: %w", apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	changed, err := r.scanCustomers(ctx, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	r.logger.InfoContext(ctx, "Customer delinquency statuses updated successfully", slog.Int("changed", len(changed)))
+	return changed, nil
+}
+
+const markDelinquencyNotifiedQuery = `
+        UPDATE customers AS c SET notified_for_dpd_bucket = v.bucket, last_notified_delinquency_at = $3
+        FROM unnest($1::bigint[], $2::text[]) AS v(id, bucket)
+        WHERE c.id = v.id AND c.notified_for_dpd_bucket IS DISTINCT FROM v.bucket
+        RETURNING c.id`
+
+func (r *CustomerRepository) MarkDelinquencyNotified(ctx context.Context, notices []customer.DelinquencyNotice) ([]int64, error) {
+	ids := make([]int64, len(notices))
+	buckets := make([]string, len(notices))
+	for i, n := range notices {
+		ids[i], buckets[i] = n.CustomerID, n.Bucket
+	}
+
+	rows, err := r.db.Query(ctx, markDelinquencyNotifiedQuery, ids, buckets, r.clock.Now())
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to execute mark delinquency notified", slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to record delinquency notices: %w", apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	notified := make([]int64, 0)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			r.logger.ErrorContext(ctx, "Failed to scan notified customer ID", slog.Any("error", err))
+			return nil, fmt.Errorf("%w: failed scanning notified customer ID: %w", apperrors.ErrDatabase, err)
+		}
+		notified = append(notified, id)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.ErrorContext(ctx, "Error iterating notified customer IDs", slog.Any("error", err))
+		return nil, fmt.Errorf("%w: error iterating notified customer IDs: %w", apperrors.ErrDatabase, err)
+	}
+	return notified, nil
+}
+
+func (r *CustomerRepository) ClearDelinquencyNotices(ctx context.Context, customerIDs []int64) error {
+	_, err := r.db.Exec(ctx, `UPDATE customers SET notified_for_dpd_bucket = NULL WHERE id = ANY($1) AND notified_for_dpd_bucket IS NOT NULL`, customerIDs)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to execute clear delinquency notices", slog.Any("error", err))
+		return fmt.Errorf("%w: failed to clear delinquency notices: %w", apperrors.ErrDatabase, err)
+	}
+	return nil
+}
+
 func (r *CustomerRepository) SetActiveStatus(ctx context.Context, customerID int64, isActive bool) error {
 
 	r.logger.InfoContext(ctx, "Attempting to set active status")
 
-	query := `UPDATE customers SET active = $1, updated_at = NOW() WHERE id = $2`
+	query, args := sqlbuilder.ScopeToTenant(ctx, `UPDATE customers SET active = $1, updated_at = $2 WHERE id = $3`, "tenant_id",
+		isActive, r.clock.Now(), customerID)
 
-	cmdTag, err := r.db.Exec(ctx, query, isActive, customerID)
+	cmdTag, err := r.db.Exec(ctx, query, args...)
 	if err != nil {
 		r.logger.ErrorContext(ctx, "Failed to execute update active status", slog.Any("error", err))
 		return fmt.Errorf("%w: failed to update active status: %w", apperrors.ErrDatabase, err)
@@ -357,3 +547,39 @@ func (r *CustomerRepository) SetActiveStatus(ctx context.Context, customerID int
 	r.logger.InfoContext(ctx, "Customer active status updated successfully")
 	return nil
 }
+
+func (r *CustomerRepository) scanCustomers(ctx context.Context, rows pgx.Rows) ([]*customer.Customer, error) {
+	customers := make([]*customer.Customer, 0)
+	for rows.Next() {
+		var cust customer.Customer
+		err := rows.Scan(
+			&cust.CustomerID,
+			&cust.PublicID,
+			&cust.Name,
+			&cust.Address,
+			&cust.IsDelinquent,
+			&cust.Active,
+			&cust.LoanID,
+			&cust.ExternalRef,
+			&cust.RiskScore,
+			&cust.RiskGrade,
+			&cust.RiskScoredAt,
+			&cust.MergedIntoID,
+			&cust.MergedAt,
+			&cust.CreateDate,
+			&cust.UpdatedAt,
+		)
+		if err != nil {
+			r.logger.ErrorContext(ctx, "Failed to scan customer row", slog.Any("error", err))
+
+			return nil, fmt.Errorf("%w: failed to scan customer row: %w", apperrors.ErrDatabase, err)
+		}
+		customers = append(customers, &cust)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.ErrorContext(ctx, "Error iterating customer rows", slog.Any("error", err))
+		return nil, fmt.Errorf("%w: error iterating customer rows: %w", apperrors.ErrDatabase, err)
+	}
+	return customers, nil
+}
@@ -2,10 +2,15 @@ package postgres
 
 import (
 	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/scope"
 	"context"
+	"errors"
 	"regexp"
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/pashagolub/pgxmock/v4"
 	"github.com/stretchr/testify/assert"
@@ -15,6 +20,7 @@ var loanID int64 = int64(123)
 
 var customerTest *customer.Customer = &customer.Customer{
 	CustomerID:   1,
+	PublicID:     uuid.MustParse("5f0c6f2e-8d3b-4c1a-9e57-2b8f4a6d1c90"),
 	Name:         "John Doe",
 	Address:      "123 Main St",
 	LoanID:       &loanID,
@@ -30,7 +36,7 @@ func setupCustomerRepo(t *testing.T) (context.Context, *CustomerRepository, pgxm
 	}
 
 	ctx := context.Background()
-	repo := NewCustomerRepository(mockPool, logger)
+	repo := NewCustomerRepository(mockPool, testClock, logger)
 
 	return ctx, repo, mockPool
 }
@@ -40,16 +46,20 @@ func TestCreateCustomerWhenSuccess(t *testing.T) {
 	defer mockPool.Close()
 
 	query := `
-	INSERT INTO customers (name, address, is_delinquent, active, loan_id, created_at, updated_at)
-	VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+	INSERT INTO customers (public_id, name, address, is_delinquent, active, loan_id, external_ref, created_at, updated_at, tenant_id)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8, $9)
 	RETURNING id, created_at, updated_at`
 
 	mockPool.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(
+		customerTest.PublicID,
 		customerTest.Name,
 		customerTest.Address,
 		customerTest.IsDelinquent,
 		customerTest.Active,
 		customerTest.LoanID,
+		customerTest.ExternalRef,
+		testClock.Now(),
+		scope.DefaultTenant,
 	).WillReturnRows(pgxmock.NewRows([]string{"id", "created_at", "update_at"}).
 		AddRow(customerTest.CustomerID, customerTest.CreateDate, customerTest.UpdatedAt))
 
@@ -69,8 +79,8 @@ func TestSaveExistingCustomerWhenSuccess(t *testing.T) {
 		is_delinquent = $3,
 		active = $4,
 		loan_id = $5,
-		updated_at = NOW()
-	WHERE id = $6`
+		updated_at = $6
+	WHERE id = $7`
 
 	mockPool.ExpectExec(regexp.QuoteMeta(query)).WithArgs(
 		customerTest.Name,
@@ -78,11 +88,13 @@ func TestSaveExistingCustomerWhenSuccess(t *testing.T) {
 		customerTest.IsDelinquent,
 		customerTest.Active,
 		customerTest.LoanID,
+		testClock.Now(),
 		customerTest.CustomerID,
 	).WillReturnResult(pgxmock.NewResult("UPDATE", 1))
 
 	err := repo.Save(ctx, customerTest)
 	assert.NoError(t, err)
+	assert.Equal(t, testClock.Now(), customerTest.UpdatedAt)
 	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
 }
 
@@ -92,16 +104,20 @@ func TestSaveNonExistingCustomerWhenSuccess(t *testing.T) {
 	customerTest.CustomerID = 0
 
 	query := `
-	INSERT INTO customers (name, address, is_delinquent, active, loan_id, created_at, updated_at)
-	VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+	INSERT INTO customers (public_id, name, address, is_delinquent, active, loan_id, external_ref, created_at, updated_at, tenant_id)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8, $9)
 	RETURNING id, created_at, updated_at`
 
 	mockPool.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(
+		customerTest.PublicID,
 		customerTest.Name,
 		customerTest.Address,
 		customerTest.IsDelinquent,
 		customerTest.Active,
 		customerTest.LoanID,
+		customerTest.ExternalRef,
+		testClock.Now(),
+		scope.DefaultTenant,
 	).WillReturnRows(pgxmock.NewRows([]string{"id", "created_at", "update_at"}).
 		AddRow(customerTest.CustomerID, customerTest.CreateDate, customerTest.UpdatedAt))
 
@@ -115,12 +131,12 @@ func TestFindCustomerByIDReturnOne(t *testing.T) {
 	defer mockPool.Close()
 
 	query := `
-	SELECT id, name, address, is_delinquent, active, loan_id, created_at, updated_at
+	SELECT id, public_id, name, address, is_delinquent, active, loan_id, external_ref, risk_score, risk_grade, risk_scored_at, merged_into_id, merged_at, created_at, updated_at
 	FROM customers
 	WHERE id = $1`
 
-	mockPool.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(customerTest.CustomerID).WillReturnRows(pgxmock.NewRows([]string{"id", "name", "address", "is_delinquent", "active", "loan_id", "created_at", "updated_at"}).
-		AddRow(customerTest.CustomerID, customerTest.Name, customerTest.Address, customerTest.IsDelinquent, customerTest.Active, customerTest.LoanID, customerTest.CreateDate, customerTest.UpdatedAt))
+	mockPool.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(customerTest.CustomerID).WillReturnRows(pgxmock.NewRows([]string{"id", "public_id", "name", "address", "is_delinquent", "active", "loan_id", "external_ref", "risk_score", "risk_grade", "risk_scored_at", "merged_into_id", "merged_at", "created_at", "updated_at"}).
+		AddRow(customerTest.CustomerID, customerTest.PublicID, customerTest.Name, customerTest.Address, customerTest.IsDelinquent, customerTest.Active, customerTest.LoanID, customerTest.ExternalRef, customerTest.RiskScore, customerTest.RiskGrade, customerTest.RiskScoredAt, customerTest.MergedIntoID, customerTest.MergedAt, customerTest.CreateDate, customerTest.UpdatedAt))
 
 	customerResult, err := repo.FindByID(ctx, customerTest.CustomerID)
 	assert.NoError(t, err)
@@ -133,7 +149,7 @@ func TestFindCustomerByIDReturnNone(t *testing.T) {
 	defer mockPool.Close()
 
 	query := `
-	SELECT id, name, address, is_delinquent, active, loan_id, created_at, updated_at
+	SELECT id, public_id, name, address, is_delinquent, active, loan_id, external_ref, risk_score, risk_grade, risk_scored_at, merged_into_id, merged_at, created_at, updated_at
 	FROM customers
 	WHERE id = $1`
 
@@ -145,39 +161,102 @@ func TestFindCustomerByIDReturnNone(t *testing.T) {
 	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
 }
 
+func TestFindCustomerByIDScopedToTenant(t *testing.T) {
+	ctx, repo, mockPool := setupCustomerRepo(t)
+	defer mockPool.Close()
+	ctx = scope.WithTenant(ctx, "tenant-a")
+
+	query := `
+	SELECT id, public_id, name, address, is_delinquent, active, loan_id, external_ref, risk_score, risk_grade, risk_scored_at, merged_into_id, merged_at, created_at, updated_at
+	FROM customers
+	WHERE id = $1 AND tenant_id = $2`
+
+	mockPool.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(customerTest.CustomerID, "tenant-a").WillReturnError(pgx.ErrNoRows)
+
+	customerResult, err := repo.FindByID(ctx, customerTest.CustomerID)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	assert.Nil(t, customerResult)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
 func TestFindCustomerByLoanIDReturnOne(t *testing.T) {
 	ctx, repo, mockPool := setupCustomerRepo(t)
 	defer mockPool.Close()
 	query := `
-	SELECT id, name, address, is_delinquent, active, loan_id, created_at, updated_at
+	SELECT id, public_id, name, address, is_delinquent, active, loan_id, external_ref, risk_score, risk_grade, risk_scored_at, merged_into_id, merged_at, created_at, updated_at
 	FROM customers
 	WHERE loan_id = $1`
 
-	mockPool.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(customerTest.LoanID).WillReturnRows(pgxmock.NewRows([]string{"id", "name", "address", "is_delinquent", "active", "loan_id", "created_at", "updated_at"}).
-		AddRow(customerTest.CustomerID, customerTest.Name, customerTest.Address, customerTest.IsDelinquent, customerTest.Active, customerTest.LoanID, customerTest.CreateDate, customerTest.UpdatedAt))
+	mockPool.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(customerTest.LoanID).WillReturnRows(pgxmock.NewRows([]string{"id", "public_id", "name", "address", "is_delinquent", "active", "loan_id", "external_ref", "risk_score", "risk_grade", "risk_scored_at", "merged_into_id", "merged_at", "created_at", "updated_at"}).
+		AddRow(customerTest.CustomerID, customerTest.PublicID, customerTest.Name, customerTest.Address, customerTest.IsDelinquent, customerTest.Active, customerTest.LoanID, customerTest.ExternalRef, customerTest.RiskScore, customerTest.RiskGrade, customerTest.RiskScoredAt, customerTest.MergedIntoID, customerTest.MergedAt, customerTest.CreateDate, customerTest.UpdatedAt))
 	customerResult, err := repo.FindByLoanID(ctx, loanID)
 	assert.NoError(t, err)
 	assert.Equal(t, customerTest.CustomerID, customerResult.CustomerID)
 	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
 }
 
-func TestFindAllThenGetAllCustomer(t *testing.T) {
+func TestFindCustomerByExternalRefReturnOne(t *testing.T) {
 	ctx, repo, mockPool := setupCustomerRepo(t)
 	defer mockPool.Close()
+	externalRef := "CRM-0001"
+	query := `
+	SELECT id, public_id, name, address, is_delinquent, active, loan_id, external_ref, risk_score, risk_grade, risk_scored_at, merged_into_id, merged_at, created_at, updated_at
+	FROM customers
+	WHERE external_ref = $1`
 
+	mockPool.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(externalRef).WillReturnRows(pgxmock.NewRows([]string{"id", "public_id", "name", "address", "is_delinquent", "active", "loan_id", "external_ref", "risk_score", "risk_grade", "risk_scored_at", "merged_into_id", "merged_at", "created_at", "updated_at"}).
+		AddRow(customerTest.CustomerID, customerTest.PublicID, customerTest.Name, customerTest.Address, customerTest.IsDelinquent, customerTest.Active, customerTest.LoanID, &externalRef, customerTest.RiskScore, customerTest.RiskGrade, customerTest.RiskScoredAt, customerTest.MergedIntoID, customerTest.MergedAt, customerTest.CreateDate, customerTest.UpdatedAt))
+	customerResult, err := repo.FindByExternalRef(ctx, externalRef)
+	assert.NoError(t, err)
+	assert.Equal(t, customerTest.CustomerID, customerResult.CustomerID)
+	assert.Equal(t, externalRef, *customerResult.ExternalRef)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestFindCustomerByExternalRefReturnNone(t *testing.T) {
+	ctx, repo, mockPool := setupCustomerRepo(t)
+	defer mockPool.Close()
 	query := `
-	SELECT id, name, address, is_delinquent, active, loan_id, created_at, updated_at
-	FROM customers WHERE active = $1`
-	args := []any{}
-	args = append(args, true)
-	query += " ORDER BY id ASC"
-
-	mockPool.ExpectQuery(regexp.QuoteMeta(query)).
-		WithArgs(args...).
-		WillReturnRows(pgxmock.NewRows([]string{"id", "name", "address", "is_delinquent", "active", "loan_id", "created_at", "updated_at"}).
-			AddRow(customerTest.CustomerID, customerTest.Name, customerTest.Address, customerTest.IsDelinquent, customerTest.Active, customerTest.LoanID, customerTest.CreateDate, customerTest.UpdatedAt))
-
-	customerResult, err := repo.FindAll(ctx, true)
+	SELECT id, public_id, name, address, is_delinquent, active, loan_id, external_ref, risk_score, risk_grade, risk_scored_at, merged_into_id, merged_at, created_at, updated_at
+	FROM customers
+	WHERE external_ref = $1`
+
+	mockPool.ExpectQuery(regexp.QuoteMeta(query)).WithArgs("missing").WillReturnError(pgx.ErrNoRows)
+
+	customerResult, err := repo.FindByExternalRef(ctx, "missing")
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	assert.Nil(t, customerResult)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestFindCustomerByPublicIDReturnOne(t *testing.T) {
+	ctx, repo, mockPool := setupCustomerRepo(t)
+	defer mockPool.Close()
+	query := `
+	SELECT id, public_id, name, address, is_delinquent, active, loan_id, external_ref, risk_score, risk_grade, risk_scored_at, merged_into_id, merged_at, created_at, updated_at
+	FROM customers
+	WHERE public_id = $1`
+
+	mockPool.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(customerTest.PublicID).WillReturnRows(pgxmock.NewRows([]string{"id", "public_id", "name", "address", "is_delinquent", "active", "loan_id", "external_ref", "risk_score", "risk_grade", "risk_scored_at", "merged_into_id", "merged_at", "created_at", "updated_at"}).
+		AddRow(customerTest.CustomerID, customerTest.PublicID, customerTest.Name, customerTest.Address, customerTest.IsDelinquent, customerTest.Active, customerTest.LoanID, customerTest.ExternalRef, customerTest.RiskScore, customerTest.RiskGrade, customerTest.RiskScoredAt, customerTest.MergedIntoID, customerTest.MergedAt, customerTest.CreateDate, customerTest.UpdatedAt))
+	customerResult, err := repo.FindByPublicID(ctx, customerTest.PublicID)
+	assert.NoError(t, err)
+	assert.Equal(t, customerTest.CustomerID, customerResult.CustomerID)
+	assert.Equal(t, customerTest.PublicID, customerResult.PublicID)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestFindAllThenGetAllCustomer(t *testing.T) {
+	ctx, repo, mockPool := setupCustomerRepo(t)
+	defer mockPool.Close()
+
+	mockPool.ExpectQuery(regexp.QuoteMeta(findAllCustomersQuery+` WHERE active = $1 AND is_delinquent = $2 ORDER BY name DESC, id LIMIT $3 OFFSET $4`)).
+		WithArgs(true, false, 10, 20).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "public_id", "name", "address", "is_delinquent", "active", "loan_id", "external_ref", "risk_score", "risk_grade", "risk_scored_at", "merged_into_id", "merged_at", "created_at", "updated_at"}).
+			AddRow(customerTest.CustomerID, customerTest.PublicID, customerTest.Name, customerTest.Address, customerTest.IsDelinquent, customerTest.Active, customerTest.LoanID, customerTest.ExternalRef, customerTest.RiskScore, customerTest.RiskGrade, customerTest.RiskScoredAt, customerTest.MergedIntoID, customerTest.MergedAt, customerTest.CreateDate, customerTest.UpdatedAt))
+
+	active, delinquent := true, false
+	customerResult, err := repo.FindAll(ctx, customer.ListFilter{Active: &active, Delinquent: &delinquent, Sort: "-name", Limit: 10, Offset: 20})
 	assert.NoError(t, err)
 	assert.Equal(t, 1, len(customerResult))
 	assert.Equal(t, customerTest.CustomerID, customerResult[0].CustomerID)
@@ -188,26 +267,45 @@ func TestFindAllThenGetAllCustomerExpectNoCustomer(t *testing.T) {
 	ctx, repo, mockPool := setupCustomerRepo(t)
 	defer mockPool.Close()
 
-	query := `
-	SELECT id, name, address, is_delinquent, active, loan_id, created_at, updated_at
-	FROM customers`
-	args := []any{}
-	query += " ORDER BY id ASC"
-
 	customerTest.Active = false
 
-	mockPool.ExpectQuery(regexp.QuoteMeta(query)).
-		WithArgs(args...).
-		WillReturnRows(pgxmock.NewRows([]string{"id", "name", "address", "is_delinquent", "active", "loan_id", "created_at", "updated_at"}).
-			AddRow(customerTest.CustomerID, customerTest.Name, customerTest.Address, customerTest.IsDelinquent, customerTest.Active, customerTest.LoanID, customerTest.CreateDate, customerTest.UpdatedAt))
+	mockPool.ExpectQuery(regexp.QuoteMeta(findAllCustomersQuery + ` ORDER BY id`)).
+		WithArgs().
+		WillReturnRows(pgxmock.NewRows([]string{"id", "public_id", "name", "address", "is_delinquent", "active", "loan_id", "external_ref", "risk_score", "risk_grade", "risk_scored_at", "merged_into_id", "merged_at", "created_at", "updated_at"}).
+			AddRow(customerTest.CustomerID, customerTest.PublicID, customerTest.Name, customerTest.Address, customerTest.IsDelinquent, customerTest.Active, customerTest.LoanID, customerTest.ExternalRef, customerTest.RiskScore, customerTest.RiskGrade, customerTest.RiskScoredAt, customerTest.MergedIntoID, customerTest.MergedAt, customerTest.CreateDate, customerTest.UpdatedAt))
 
-	customerResult, err := repo.FindAll(ctx, false)
+	customerResult, err := repo.FindAll(ctx, customer.ListFilter{})
 	assert.NoError(t, err)
 	assert.Equal(t, 1, len(customerResult))
 	assert.Equal(t, customerTest.CustomerID, customerResult[0].CustomerID)
 	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
 }
 
+func TestFindByNameContaining(t *testing.T) {
+	ctx, repo, mockPool := setupCustomerRepo(t)
+	defer mockPool.Close()
+
+	mockPool.ExpectQuery(regexp.QuoteMeta(findAllCustomersQuery+` WHERE active = $1 AND lower(name) LIKE lower($2) ESCAPE '\' ORDER BY id LIMIT $3`)).
+		WithArgs(true, `%50\% doe%`, 500).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "public_id", "name", "address", "is_delinquent", "active", "loan_id", "external_ref", "risk_score", "risk_grade", "risk_scored_at", "merged_into_id", "merged_at", "created_at", "updated_at"}).
+			AddRow(customerTest.CustomerID, customerTest.PublicID, customerTest.Name, customerTest.Address, customerTest.IsDelinquent, customerTest.Active, customerTest.LoanID, customerTest.ExternalRef, customerTest.RiskScore, customerTest.RiskGrade, customerTest.RiskScoredAt, customerTest.MergedIntoID, customerTest.MergedAt, customerTest.CreateDate, customerTest.UpdatedAt))
+
+	found, err := repo.FindByNameContaining(ctx, "50% doe", 500)
+	assert.NoError(t, err)
+	assert.Len(t, found, 1)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestFindAllRejectsUnknownSortField(t *testing.T) {
+	ctx, repo, mockPool := setupCustomerRepo(t)
+	defer mockPool.Close()
+
+	_, err := repo.FindAll(ctx, customer.ListFilter{Sort: "address"})
+
+	assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), "nothing is sent to the database")
+}
+
 func TestSetDelinquencyStatusWhenSuccess(t *testing.T) {
 	ctx, repo, mockPool := setupCustomerRepo(t)
 	defer mockPool.Close()
@@ -215,11 +313,12 @@ func TestSetDelinquencyStatusWhenSuccess(t *testing.T) {
 	query := `
 	UPDATE customers
 	SET is_delinquent = $1,
-		updated_at = NOW()
-	WHERE id = $2`
+		updated_at = $2
+	WHERE id = $3`
 
 	mockPool.ExpectExec(regexp.QuoteMeta(query)).WithArgs(
 		customerTest.IsDelinquent,
+		testClock.Now(),
 		customerTest.CustomerID,
 	).WillReturnResult(pgxmock.NewResult("UPDATE", 1))
 
@@ -234,11 +333,12 @@ func TestSetDelinquencyStatusWhenError(t *testing.T) {
 	query := `
 	UPDATE customers
 	SET is_delinquent = $1,
-		updated_at = NOW()
-	WHERE id = $2`
+		updated_at = $2
+	WHERE id = $3`
 
 	mockPool.ExpectExec(regexp.QuoteMeta(query)).WithArgs(
 		customerTest.IsDelinquent,
+		testClock.Now(),
 		customerTest.CustomerID,
 	).WillReturnError(pgx.ErrNoRows)
 
@@ -246,6 +346,73 @@ func TestSetDelinquencyStatusWhenError(t *testing.T) {
 	assert.Error(t, err)
 	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
 }
+func TestSetDelinquencyStatusBulk(t *testing.T) {
+	ctx, repo, mockPool := setupCustomerRepo(t)
+	defer mockPool.Close()
+
+	mockPool.ExpectQuery(regexp.QuoteMeta(setDelinquencyStatusBulkQuery)).
+		WithArgs([]int64{customerTest.CustomerID, 99}, []bool{true, false}, testClock.Now()).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "public_id", "name", "address", "is_delinquent", "active", "loan_id", "external_ref", "risk_score", "risk_grade", "risk_scored_at", "merged_into_id", "merged_at", "created_at", "updated_at"}).
+			AddRow(customerTest.CustomerID, customerTest.PublicID, customerTest.Name, customerTest.Address, true, customerTest.Active, customerTest.LoanID, customerTest.ExternalRef, customerTest.RiskScore, customerTest.RiskGrade, customerTest.RiskScoredAt, customerTest.MergedIntoID, customerTest.MergedAt, customerTest.CreateDate, customerTest.UpdatedAt))
+
+	changed, err := repo.SetDelinquencyStatusBulk(ctx, []customer.CustomerDelinquency{
+		{CustomerID: customerTest.CustomerID, IsDelinquent: true},
+		{CustomerID: 99, IsDelinquent: false},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, changed, 1)
+	assert.True(t, changed[0].IsDelinquent)
+
+	mockPool.ExpectQuery(regexp.QuoteMeta(setDelinquencyStatusBulkQuery)).
+		WithArgs([]int64{customerTest.CustomerID}, []bool{true}, testClock.Now()).
+		WillReturnError(errors.New("connection reset"))
+	_, err = repo.SetDelinquencyStatusBulk(ctx, []customer.CustomerDelinquency{{CustomerID: customerTest.CustomerID, IsDelinquent: true}})
+	assert.ErrorIs(t, err, apperrors.ErrDatabase)
+
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestMarkDelinquencyNotified(t *testing.T) {
+	ctx, repo, mockPool := setupCustomerRepo(t)
+	defer mockPool.Close()
+
+	mockPool.ExpectQuery(regexp.QuoteMeta(markDelinquencyNotifiedQuery)).
+		WithArgs([]int64{customerTest.CustomerID, 99}, []string{"31-60", "1-30"}, testClock.Now()).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(customerTest.CustomerID))
+
+	notified, err := repo.MarkDelinquencyNotified(ctx, []customer.DelinquencyNotice{
+		{CustomerID: customerTest.CustomerID, LoanID: 5, DaysPastDue: 40, Bucket: "31-60"},
+		{CustomerID: 99, LoanID: 6, DaysPastDue: 3, Bucket: "1-30"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{customerTest.CustomerID}, notified)
+
+	mockPool.ExpectQuery(regexp.QuoteMeta(markDelinquencyNotifiedQuery)).
+		WithArgs([]int64{99}, []string{"1-30"}, testClock.Now()).
+		WillReturnError(errors.New("connection reset"))
+	_, err = repo.MarkDelinquencyNotified(ctx, []customer.DelinquencyNotice{{CustomerID: 99, Bucket: "1-30"}})
+	assert.ErrorIs(t, err, apperrors.ErrDatabase)
+
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestClearDelinquencyNotices(t *testing.T) {
+	ctx, repo, mockPool := setupCustomerRepo(t)
+	defer mockPool.Close()
+
+	mockPool.ExpectExec(regexp.QuoteMeta(`UPDATE customers SET notified_for_dpd_bucket = NULL WHERE id = ANY($1) AND notified_for_dpd_bucket IS NOT NULL`)).
+		WithArgs([]int64{3, 4}).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	assert.NoError(t, repo.ClearDelinquencyNotices(ctx, []int64{3, 4}))
+
+	mockPool.ExpectExec(regexp.QuoteMeta(`UPDATE customers SET notified_for_dpd_bucket = NULL`)).
+		WithArgs([]int64{5}).
+		WillReturnError(errors.New("connection reset"))
+	assert.ErrorIs(t, repo.ClearDelinquencyNotices(ctx, []int64{5}), apperrors.ErrDatabase)
+
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
 func TestSetActiveStatusWhenSuccess(t *testing.T) {
 	ctx, repo, mockPool := setupCustomerRepo(t)
 	defer mockPool.Close()
@@ -253,11 +420,12 @@ func TestSetActiveStatusWhenSuccess(t *testing.T) {
 	query := `
 	UPDATE customers
 	SET active = $1,
-		updated_at = NOW()
-	WHERE id = $2`
+		updated_at = $2
+	WHERE id = $3`
 
 	mockPool.ExpectExec(regexp.QuoteMeta(query)).WithArgs(
 		customerTest.Active,
+		testClock.Now(),
 		customerTest.CustomerID,
 	).WillReturnResult(pgxmock.NewResult("UPDATE", 1))
 
@@ -272,11 +440,12 @@ func TestSetActiveStatusWhenError(t *testing.T) {
 	query := `
 	UPDATE customers
 	SET active = $1,
-		updated_at = NOW()
-	WHERE id = $2`
+		updated_at = $2
+	WHERE id = $3`
 
 	mockPool.ExpectExec(regexp.QuoteMeta(query)).WithArgs(
 		customerTest.Active,
+		testClock.Now(),
 		customerTest.CustomerID,
 	).WillReturnError(pgx.ErrNoRows)
 
@@ -285,6 +454,44 @@ func TestSetActiveStatusWhenError(t *testing.T) {
 	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
 }
 
+func TestSetRiskScore(t *testing.T) {
+	ctx, repo, mockPool := setupCustomerRepo(t)
+	defer mockPool.Close()
+
+	query := `UPDATE customers SET risk_score = $1, risk_grade = $2, risk_scored_at = $3, updated_at = $4 WHERE id = $5`
+	scoredAt := testClock.Now().Add(-time.Hour)
+
+	mockPool.ExpectExec(regexp.QuoteMeta(query)).WithArgs(712, "B+", scoredAt, testClock.Now(), int64(1)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	assert.NoError(t, repo.SetRiskScore(ctx, &customer.RiskAssessment{CustomerID: 1, Score: 712, Grade: "B+", ScoredAt: scoredAt}))
+
+	mockPool.ExpectExec(regexp.QuoteMeta(query)).WithArgs(712, "B+", scoredAt, testClock.Now(), int64(404)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+	err := repo.SetRiskScore(ctx, &customer.RiskAssessment{CustomerID: 404, Score: 712, Grade: "B+", ScoredAt: scoredAt})
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestFindCustomerByIDReturnsRiskScore(t *testing.T) {
+	ctx, repo, mockPool := setupCustomerRepo(t)
+	defer mockPool.Close()
+	score, grade, scoredAt := 712, "B+", testClock.Now()
+
+	mockPool.ExpectQuery(`SELECT id, public_id, .* FROM customers WHERE id = \$1`).WithArgs(int64(2)).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "public_id", "name", "address", "is_delinquent", "active", "loan_id", "external_ref", "risk_score", "risk_grade", "risk_scored_at", "merged_into_id", "merged_at", "created_at", "updated_at"}).
+			AddRow(int64(2), customerTest.PublicID, "Jane", "1 Main St", false, true, (*int64)(nil), (*string)(nil), &score, &grade, &scoredAt, (*int64)(nil), (*time.Time)(nil), testClock.Now(), testClock.Now()))
+
+	cust, err := repo.FindByID(ctx, 2)
+
+	assert.NoError(t, err)
+	assert.True(t, cust.Scored())
+	assert.Equal(t, 712, *cust.RiskScore)
+	assert.Equal(t, "B+", *cust.RiskGrade)
+	assert.Equal(t, scoredAt, *cust.RiskScoredAt)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
 func TestDeleteCustomerWhenSuccess(t *testing.T) {
 	ctx, repo, mockPool := setupCustomerRepo(t)
 	defer mockPool.Close()
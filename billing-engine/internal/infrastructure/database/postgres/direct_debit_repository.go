@@ -0,0 +1,261 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"billing-engine/internal/domain/directdebit"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	mandateColumns = `m.id, m.customer_id, m.reference, m.account_holder, m.account_number, m.bank_code, m.status, m.signed_at, m.created_at, m.updated_at`
+
+	instructionColumns = `i.id, i.mandate_id, i.loan_id, i.schedule_id, i.week_number, i.end_to_end_id, i.amount, i.collection_date,
+        i.status, COALESCE(i.batch_ref, ''), COALESCE(i.failure_reason, ''), i.created_at, i.updated_at`
+)
+
+type DirectDebitRepository struct {
+	db     DBPool
+	clock  clock.Clock
+	logger *slog.Logger
+}
+
+var _ directdebit.Repository = (*DirectDebitRepository)(nil)
+
+// NewDirectDebitRepository builds the mandate and instruction repository;
+// clk stamps created_at and updated_at and nil means the wall clock.
+func NewDirectDebitRepository(db DBPool, clk clock.Clock, logger *slog.Logger) *DirectDebitRepository {
+	if db == nil {
+		panic("DBPool cannot be nil for DirectDebitRepository")
+	}
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+		logger.Warn("Warning: No logger provided to NewDirectDebitRepository, using default stderr handler")
+	}
+	return &DirectDebitRepository{
+		db:     db,
+		clock:  clock.OrSystem(clk),
+		logger: logger.With("component", "DirectDebitRepository"),
+	}
+}
+
+func scanMandate(row pgx.Row, m *directdebit.Mandate) error {
+	return row.Scan(&m.ID, &m.CustomerID, &m.Reference, &m.AccountHolder, &m.AccountNumber, &m.BankCode,
+		&m.Status, &m.SignedAt, &m.CreatedAt, &m.UpdatedAt)
+}
+
+func instructionFields(in *directdebit.Instruction) []any {
+	return []any{&in.ID, &in.MandateID, &in.LoanID, &in.ScheduleID, &in.WeekNumber, &in.EndToEndID, &in.Amount, &in.CollectionDate,
+		&in.Status, &in.BatchRef, &in.FailureReason, &in.CreatedAt, &in.UpdatedAt}
+}
+
+func (r *DirectDebitRepository) CreateMandate(ctx context.Context, m *directdebit.Mandate) error {
+	query := `
+        INSERT INTO mandates (customer_id, reference, account_holder, account_number, bank_code, status, signed_at, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8)
+        RETURNING id, created_at, updated_at`
+
+	err := r.db.QueryRow(ctx, query, m.CustomerID, m.Reference, m.AccountHolder, m.AccountNumber, m.BankCode, m.Status, m.SignedAt, r.clock.Now()).
+		Scan(&m.ID, &m.CreatedAt, &m.UpdatedAt)
+	if err == nil {
+		return nil
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch {
+		case pgErr.Code == "23503":
+			return fmt.Errorf("%w: customer %d", apperrors.ErrNotFound, m.CustomerID)
+		case pgErr.Code == "23505" && pgErr.ConstraintName == "uq_mandates_active_customer":
+			return fmt.Errorf("%w: customer %d already has an active mandate", apperrors.ErrConflict, m.CustomerID)
+		case pgErr.Code == "23505":
+			return fmt.Errorf("%w: mandate reference %q", apperrors.ErrAlreadyExists, m.Reference)
+		}
+	}
+	r.logger.ErrorContext(ctx, "Failed to insert mandate", slog.Int64("customerID", m.CustomerID), slog.Any("error", err))
+	return fmt.Errorf("%w: failed to insert mandate: %w", apperrors.ErrDatabase, err)
+}
+
+func (r *DirectDebitRepository) ListMandates(ctx context.Context, customerID int64) ([]directdebit.Mandate, error) {
+	query := `SELECT ` + mandateColumns + ` FROM mandates m WHERE m.customer_id = $1 ORDER BY m.created_at DESC, m.id DESC`
+
+	rows, err := r.db.Query(ctx, query, customerID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query mandates", slog.Int64("customerID", customerID), slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to list mandates: %w", apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	mandates := []directdebit.Mandate{}
+	for rows.Next() {
+		var m directdebit.Mandate
+		if err := scanMandate(rows, &m); err != nil {
+			return nil, fmt.Errorf("%w: failed to scan mandate: %w", apperrors.ErrDatabase, err)
+		}
+		mandates = append(mandates, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: failed to iterate mandates: %w", apperrors.ErrDatabase, err)
+	}
+	return mandates, nil
+}
+
+func (r *DirectDebitRepository) CancelMandate(ctx context.Context, customerID, mandateID int64) error {
+	query := `UPDATE mandates SET status = $1, updated_at = $2 WHERE id = $3 AND customer_id = $4 AND status = $5`
+	tag, err := r.db.Exec(ctx, query, directdebit.MandateCancelled, r.clock.Now(), mandateID, customerID, directdebit.MandateActive)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to cancel mandate", slog.Int64("mandateID", mandateID), slog.Any("error", err))
+		return fmt.Errorf("%w: failed to cancel mandate: %w", apperrors.ErrDatabase, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: active mandate %d of customer %d", apperrors.ErrNotFound, mandateID, customerID)
+	}
+	return nil
+}
+
+func (r *DirectDebitRepository) ListUncoveredInstallments(ctx context.Context, through time.Time) ([]directdebit.Installment, error) {
+	query := `
+        SELECT ` + mandateColumns + `, s.loan_id, s.id, s.week_number, s.due_date, s.due_amount - s.paid_amount
+        FROM loan_schedule s
+        JOIN loans l ON l.id = s.loan_id
+        JOIN customers c ON c.loan_id = l.id
+        JOIN mandates m ON m.customer_id = c.id AND m.status = 'ACTIVE'
+        WHERE s.status != 'PAID' AND s.due_date <= $1 AND l.status != 'PAID_OFF'
+          AND NOT EXISTS (
+            SELECT 1 FROM direct_debit_instructions i
+            WHERE i.schedule_id = s.id AND i.status != 'FAILED'
+          )
+          AND NOT EXISTS (
+            SELECT 1 FROM loan_holds h
+            WHERE h.loan_id = l.id AND h.released_at IS NULL
+          )
+        ORDER BY s.due_date, s.loan_id, s.week_number`
+
+	rows, err := r.db.Query(ctx, query, through)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query uncovered installments", slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to list installments: %w", apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	installments := []directdebit.Installment{}
+	for rows.Next() {
+		var in directdebit.Installment
+		m := &in.Mandate
+		err := rows.Scan(&m.ID, &m.CustomerID, &m.Reference, &m.AccountHolder, &m.AccountNumber, &m.BankCode,
+			&m.Status, &m.SignedAt, &m.CreatedAt, &m.UpdatedAt,
+			&in.LoanID, &in.ScheduleID, &in.WeekNumber, &in.DueDate, &in.Amount)
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to scan installment: %w", apperrors.ErrDatabase, err)
+		}
+		installments = append(installments, in)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: failed to iterate installments: %w", apperrors.ErrDatabase, err)
+	}
+	return installments, nil
+}
+
+func (r *DirectDebitRepository) CreateInstruction(ctx context.Context, in *directdebit.Instruction) error {
+	query := `
+        INSERT INTO direct_debit_instructions (mandate_id, loan_id, schedule_id, week_number, end_to_end_id, amount, collection_date, status, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9)
+        RETURNING id, created_at, updated_at`
+
+	err := r.db.QueryRow(ctx, query, in.MandateID, in.LoanID, in.ScheduleID, in.WeekNumber, in.EndToEndID, in.Amount, in.CollectionDate, in.Status, r.clock.Now()).
+		Scan(&in.ID, &in.CreatedAt, &in.UpdatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return fmt.Errorf("%w: instruction for schedule entry %d", apperrors.ErrAlreadyExists, in.ScheduleID)
+		}
+		r.logger.ErrorContext(ctx, "Failed to insert instruction", slog.Int64("scheduleID", in.ScheduleID), slog.Any("error", err))
+		return fmt.Errorf("%w: failed to insert instruction: %w", apperrors.ErrDatabase, err)
+	}
+	return nil
+}
+
+func (r *DirectDebitRepository) ListInstructions(ctx context.Context, status directdebit.InstructionStatus) ([]directdebit.Instruction, error) {
+	query := `
+        SELECT ` + instructionColumns + `, ` + mandateColumns + `
+        FROM direct_debit_instructions i
+        JOIN mandates m ON m.id = i.mandate_id
+        WHERE i.status = $1
+        ORDER BY i.collection_date, i.id`
+
+	rows, err := r.db.Query(ctx, query, status)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query instructions", slog.String("status", string(status)), slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to list instructions: %w", apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	instructions := []directdebit.Instruction{}
+	for rows.Next() {
+		var in directdebit.Instruction
+		m := &in.Mandate
+		fields := append(instructionFields(&in), &m.ID, &m.CustomerID, &m.Reference, &m.AccountHolder, &m.AccountNumber, &m.BankCode,
+			&m.Status, &m.SignedAt, &m.CreatedAt, &m.UpdatedAt)
+		if err := rows.Scan(fields...); err != nil {
+			return nil, fmt.Errorf("%w: failed to scan instruction: %w", apperrors.ErrDatabase, err)
+		}
+		instructions = append(instructions, in)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: failed to iterate instructions: %w", apperrors.ErrDatabase, err)
+	}
+	return instructions, nil
+}
+
+func (r *DirectDebitRepository) MarkExported(ctx context.Context, ids []int64, batchRef string) (int64, error) {
+	query := `
+        UPDATE direct_debit_instructions
+        SET status = $1, batch_ref = $2, updated_at = $3
+        WHERE id = ANY($4) AND status = $5`
+
+	tag, err := r.db.Exec(ctx, query, directdebit.InstructionExported, batchRef, r.clock.Now(), ids, directdebit.InstructionPending)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to mark instructions exported", slog.String("batchRef", batchRef), slog.Any("error", err))
+		return 0, fmt.Errorf("%w: failed to mark instructions exported: %w", apperrors.ErrDatabase, err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (r *DirectDebitRepository) GetInstructionByEndToEndID(ctx context.Context, endToEndID string) (*directdebit.Instruction, error) {
+	query := `SELECT ` + instructionColumns + ` FROM direct_debit_instructions i WHERE i.end_to_end_id = $1`
+
+	var in directdebit.Instruction
+	if err := r.db.QueryRow(ctx, query, endToEndID).Scan(instructionFields(&in)...); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%w: instruction %q", apperrors.ErrNotFound, endToEndID)
+		}
+		r.logger.ErrorContext(ctx, "Failed to get instruction", slog.String("endToEndID", endToEndID), slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to get instruction: %w", apperrors.ErrDatabase, err)
+	}
+	return &in, nil
+}
+
+func (r *DirectDebitRepository) TransitionInstruction(ctx context.Context, id int64, from, to directdebit.InstructionStatus, reason string) error {
+	query := `
+        UPDATE direct_debit_instructions
+        SET status = $1, failure_reason = NULLIF($2, ''), updated_at = $3
+        WHERE id = $4 AND status = $5`
+
+	tag, err := r.db.Exec(ctx, query, to, reason, r.clock.Now(), id, from)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to update instruction", slog.Int64("instructionID", id), slog.Any("error", err))
+		return fmt.Errorf("%w: failed to update instruction: %w", apperrors.ErrDatabase, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: instruction %d is no longer %s", apperrors.ErrConflict, id, from)
+	}
+	return nil
+}
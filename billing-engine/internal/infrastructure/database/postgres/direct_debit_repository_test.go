@@ -0,0 +1,195 @@
+package postgres
+
+import (
+	"billing-engine/internal/domain/directdebit"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupDirectDebitRepo(t *testing.T) (context.Context, *DirectDebitRepository, pgxmock.PgxPoolIface) {
+	t.Helper()
+	mockPool, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("failed to open a stub database connection: %v", err)
+	}
+	return context.Background(), NewDirectDebitRepository(mockPool, testClock, logger), mockPool
+}
+
+var mandateColumnNames = []string{"id", "customer_id", "reference", "account_holder", "account_number", "bank_code", "status", "signed_at", "created_at", "updated_at"}
+
+var instructionColumnNames = []string{"id", "mandate_id", "loan_id", "schedule_id", "week_number", "end_to_end_id", "amount", "collection_date",
+	"status", "batch_ref", "failure_reason", "created_at", "updated_at"}
+
+func anyArgs(n int) []any {
+	args := make([]any, n)
+	for i := range args {
+		args[i] = pgxmock.AnyArg()
+	}
+	return args
+}
+
+func newDirectDebitMandate() *directdebit.Mandate {
+	return &directdebit.Mandate{
+		CustomerID: 9, Reference: "MNDT-9", AccountHolder: "Jane Doe", AccountNumber: "9876543210", BankCode: "BANKIDJB",
+		Status: directdebit.MandateActive, SignedAt: time.Date(2024, 12, 20, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestDirectDebitRepositoryCreateMandate(t *testing.T) {
+	ctx, repo, mockPool := setupDirectDebitRepo(t)
+	defer mockPool.Close()
+
+	m := newDirectDebitMandate()
+	mockPool.ExpectQuery(`INSERT INTO mandates`).
+		WithArgs(int64(9), "MNDT-9", "Jane Doe", "9876543210", "BANKIDJB", directdebit.MandateActive, m.SignedAt, testClock.Now()).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(int64(4), testClock.Now(), testClock.Now()))
+
+	require.NoError(t, repo.CreateMandate(ctx, m))
+	assert.Equal(t, int64(4), m.ID)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestDirectDebitRepositoryCreateMandateErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		pgErr   *pgconn.PgError
+		wantErr error
+	}{
+		{"unknown customer", &pgconn.PgError{Code: "23503", ConstraintName: "mandates_customer_id_fkey"}, apperrors.ErrNotFound},
+		{"second active mandate", &pgconn.PgError{Code: "23505", ConstraintName: "uq_mandates_active_customer"}, apperrors.ErrConflict},
+		{"reference in use", &pgconn.PgError{Code: "23505", ConstraintName: "uq_mandates_reference"}, apperrors.ErrAlreadyExists},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, repo, mockPool := setupDirectDebitRepo(t)
+			defer mockPool.Close()
+
+			mockPool.ExpectQuery(`INSERT INTO mandates`).WithArgs(anyArgs(8)...).WillReturnError(tt.pgErr)
+
+			assert.ErrorIs(t, repo.CreateMandate(ctx, newDirectDebitMandate()), tt.wantErr)
+			assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+		})
+	}
+}
+
+func TestDirectDebitRepositoryCancelMandateNotActive(t *testing.T) {
+	ctx, repo, mockPool := setupDirectDebitRepo(t)
+	defer mockPool.Close()
+
+	mockPool.ExpectExec(`UPDATE mandates SET status = \$1, updated_at = \$2 WHERE id = \$3 AND customer_id = \$4 AND status = \$5`).
+		WithArgs(directdebit.MandateCancelled, testClock.Now(), int64(4), int64(9), directdebit.MandateActive).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	assert.ErrorIs(t, repo.CancelMandate(ctx, 9, 4), apperrors.ErrNotFound)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestDirectDebitRepositoryListUncoveredInstallments(t *testing.T) {
+	ctx, repo, mockPool := setupDirectDebitRepo(t)
+	defer mockPool.Close()
+
+	through := time.Date(2025, 1, 13, 0, 0, 0, 0, time.UTC)
+	m := newDirectDebitMandate()
+	columns := append(append([]string{}, mandateColumnNames...), "loan_id", "schedule_id", "week_number", "due_date", "amount")
+	mockPool.ExpectQuery(`FROM loan_schedule s .* WHERE s.status != 'PAID' AND s.due_date <= \$1`).
+		WithArgs(through).
+		WillReturnRows(pgxmock.NewRows(columns).
+			AddRow(int64(4), m.CustomerID, m.Reference, m.AccountHolder, m.AccountNumber, m.BankCode, m.Status, m.SignedAt, testClock.Now(), testClock.Now(),
+				int64(5), int64(50), 2, through, 110.0))
+
+	installments, err := repo.ListUncoveredInstallments(ctx, through)
+
+	require.NoError(t, err)
+	require.Len(t, installments, 1)
+	assert.Equal(t, int64(4), installments[0].Mandate.ID)
+	assert.Equal(t, int64(50), installments[0].ScheduleID)
+	assert.Equal(t, 110.0, installments[0].Amount)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestDirectDebitRepositoryCreateInstructionAlreadyCovered(t *testing.T) {
+	ctx, repo, mockPool := setupDirectDebitRepo(t)
+	defer mockPool.Close()
+
+	mockPool.ExpectQuery(`INSERT INTO direct_debit_instructions`).
+		WithArgs(anyArgs(9)...).
+		WillReturnError(&pgconn.PgError{Code: "23505", ConstraintName: "uq_direct_debit_instructions_open_schedule"})
+
+	err := repo.CreateInstruction(ctx, &directdebit.Instruction{MandateID: 4, LoanID: 5, ScheduleID: 50, WeekNumber: 2, Amount: 110})
+
+	assert.ErrorIs(t, err, apperrors.ErrAlreadyExists)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestDirectDebitRepositoryListInstructions(t *testing.T) {
+	ctx, repo, mockPool := setupDirectDebitRepo(t)
+	defer mockPool.Close()
+
+	m := newDirectDebitMandate()
+	date := time.Date(2025, 1, 8, 0, 0, 0, 0, time.UTC)
+	columns := append(append([]string{}, instructionColumnNames...), mandateColumnNames...)
+	mockPool.ExpectQuery(`FROM direct_debit_instructions i JOIN mandates m ON m.id = i.mandate_id WHERE i.status = \$1`).
+		WithArgs(directdebit.InstructionPending).
+		WillReturnRows(pgxmock.NewRows(columns).
+			AddRow(int64(1), int64(4), int64(5), int64(50), 2, "E2E1", 110.0, date, directdebit.InstructionPending, "", "", testClock.Now(), testClock.Now(),
+				int64(4), m.CustomerID, m.Reference, m.AccountHolder, m.AccountNumber, m.BankCode, m.Status, m.SignedAt, testClock.Now(), testClock.Now()))
+
+	instructions, err := repo.ListInstructions(ctx, directdebit.InstructionPending)
+
+	require.NoError(t, err)
+	require.Len(t, instructions, 1)
+	assert.Equal(t, "E2E1", instructions[0].EndToEndID)
+	assert.Equal(t, "MNDT-9", instructions[0].Mandate.Reference)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestDirectDebitRepositoryMarkExported(t *testing.T) {
+	ctx, repo, mockPool := setupDirectDebitRepo(t)
+	defer mockPool.Close()
+
+	mockPool.ExpectExec(`UPDATE direct_debit_instructions SET status = \$1, batch_ref = \$2, updated_at = \$3 WHERE id = ANY\(\$4\) AND status = \$5`).
+		WithArgs(directdebit.InstructionExported, "DD-20250106-AB", testClock.Now(), []int64{1, 2}, directdebit.InstructionPending).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 2))
+
+	n, err := repo.MarkExported(ctx, []int64{1, 2}, "DD-20250106-AB")
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestDirectDebitRepositoryGetInstructionNotFound(t *testing.T) {
+	ctx, repo, mockPool := setupDirectDebitRepo(t)
+	defer mockPool.Close()
+
+	mockPool.ExpectQuery(`FROM direct_debit_instructions i WHERE i.end_to_end_id = \$1`).
+		WithArgs("NOPE").
+		WillReturnRows(pgxmock.NewRows(instructionColumnNames))
+
+	in, err := repo.GetInstructionByEndToEndID(ctx, "NOPE")
+
+	assert.Nil(t, in)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestDirectDebitRepositoryTransitionInstructionConflict(t *testing.T) {
+	ctx, repo, mockPool := setupDirectDebitRepo(t)
+	defer mockPool.Close()
+
+	mockPool.ExpectExec(`UPDATE direct_debit_instructions SET status = \$1, failure_reason = NULLIF\(\$2, ''\), updated_at = \$3 WHERE id = \$4 AND status = \$5`).
+		WithArgs(directdebit.InstructionFailed, "AM04", testClock.Now(), int64(1), directdebit.InstructionExported).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	err := repo.TransitionInstruction(ctx, 1, directdebit.InstructionExported, directdebit.InstructionFailed, "AM04")
+
+	assert.ErrorIs(t, err, apperrors.ErrConflict)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
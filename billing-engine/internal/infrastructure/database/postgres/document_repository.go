@@ -0,0 +1,258 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"billing-engine/internal/domain/document"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	documentColumns = `id, loan_id, customer_id, type, status, file_name, content_type, size_bytes, storage_key, COALESCE(uploaded_by, ''), created_at`
+
+	// listUncertifiedLoansQuery dates a loan's payoff like the archive does:
+	// its last payment, or its last update when it had none.
+	listUncertifiedLoansQuery = `
+        SELECT l.id, l.public_id, COALESCE(l.external_ref, ''), c.id, COALESCE(c.name, ''),
+               l.principal_amount, l.total_loan_amount, l.start_date, ` + loanCompletedAt + `
+        FROM loans l
+        LEFT JOIN customers c ON c.loan_id = l.id
+        LEFT JOIN loan_schedule s ON s.loan_id = l.id
+        WHERE l.status = 'PAID_OFF'
+          AND NOT EXISTS (SELECT 1 FROM documents d WHERE d.loan_id = l.id AND d.type = 'PAYOFF_CERTIFICATE')
+        GROUP BY l.id, c.id
+        HAVING ` + loanCompletedAt + ` >= $1
+        ORDER BY ` + loanCompletedAt + `, l.id
+        LIMIT $2`
+
+	// listExpiredDocumentsQuery keeps the documents of a loan under hold
+	// along with the loan.
+	listExpiredDocumentsQuery = `
+        SELECT ` + documentColumns + ` FROM documents d
+        WHERE d.type = $1 AND d.status = 'AVAILABLE' AND d.created_at < $2
+          AND NOT EXISTS (SELECT 1 FROM loan_holds h WHERE h.loan_id = d.loan_id AND h.released_at IS NULL)
+        ORDER BY d.created_at, d.id
+        LIMIT $3`
+
+	listStalePendingDocumentsQuery = `
+        SELECT ` + documentColumns + ` FROM documents
+        WHERE status = 'PENDING' AND created_at < $1
+        ORDER BY created_at, id
+        LIMIT $2`
+)
+
+type DocumentRepository struct {
+	db     DBPool
+	clock  clock.Clock
+	logger *slog.Logger
+}
+
+var _ document.Repository = (*DocumentRepository)(nil)
+
+// NewDocumentRepository builds the document repository; clk stamps
+// created_at and nil means the wall clock.
+func NewDocumentRepository(db DBPool, clk clock.Clock, logger *slog.Logger) *DocumentRepository {
+	if db == nil {
+		panic("DBPool cannot be nil for DocumentRepository")
+	}
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+		logger.Warn("Warning: No logger provided to NewDocumentRepository, using default stderr handler")
+	}
+	return &DocumentRepository{
+		db:     db,
+		clock:  clock.OrSystem(clk),
+		logger: logger.With("component", "DocumentRepository"),
+	}
+}
+
+func (r *DocumentRepository) ListUncertified(ctx context.Context, since time.Time, limit int) ([]document.PaidOffLoan, error) {
+	rows, err := r.db.Query(ctx, listUncertifiedLoansQuery, since, limit)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query loans awaiting a payoff certificate", slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to list loans awaiting a payoff certificate: %w", apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	loans := []document.PaidOffLoan{}
+	for rows.Next() {
+		var l document.PaidOffLoan
+		if err := rows.Scan(&l.LoanID, &l.PublicID, &l.ExternalRef, &l.CustomerID, &l.CustomerName,
+			&l.Principal, &l.TotalRepaid, &l.StartDate, &l.PaidOffAt); err != nil {
+			return nil, fmt.Errorf("%w: failed to scan paid-off loan: %w", apperrors.ErrDatabase, err)
+		}
+		loans = append(loans, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: failed to iterate paid-off loans: %w", apperrors.ErrDatabase, err)
+	}
+	return loans, nil
+}
+
+// documentSubjectColumn returns the column that references subject and
+// the table it references, from a fixed set that is safe to splice into
+// SQL.
+func documentSubjectColumn(subject document.Subject) (column, table string, err error) {
+	switch subject.Type {
+	case document.SubjectLoan:
+		return "loan_id", "loans", nil
+	case document.SubjectCustomer:
+		return "customer_id", "customers", nil
+	}
+	return "", "", fmt.Errorf("%w: unknown subject type %q", apperrors.ErrInvalidArgument, subject.Type)
+}
+
+func (r *DocumentRepository) Create(ctx context.Context, d *document.Document) error {
+	column, _, err := documentSubjectColumn(d.Subject)
+	if err != nil {
+		return err
+	}
+	query := fmt.Sprintf(`
+        INSERT INTO documents (%s, type, status, file_name, content_type, size_bytes, storage_key, uploaded_by, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), $9)
+        RETURNING id, created_at`, column)
+	err = r.db.QueryRow(ctx, query, d.Subject.ID, d.Type, d.Status, d.FileName, d.ContentType, d.SizeBytes, d.StorageKey, d.UploadedBy, r.clock.Now()).
+		Scan(&d.ID, &d.CreatedAt)
+	if err == nil {
+		return nil
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23503":
+			return fmt.Errorf("%w: %s", apperrors.ErrNotFound, d.Subject)
+		case "23505":
+			return fmt.Errorf("%w: %s already has a %s", apperrors.ErrConflict, d.Subject, d.Type)
+		}
+	}
+	r.logger.ErrorContext(ctx, "Failed to insert document", slog.String("subject", d.Subject.String()), slog.Any("error", err))
+	return fmt.Errorf("%w: failed to insert document: %w", apperrors.ErrDatabase, err)
+}
+
+func (r *DocumentRepository) List(ctx context.Context, subject document.Subject) ([]document.Document, error) {
+	column, _, err := documentSubjectColumn(subject)
+	if err != nil {
+		return nil, err
+	}
+	query := fmt.Sprintf(`SELECT %s FROM documents WHERE %s = $1 ORDER BY created_at DESC, id DESC`, documentColumns, column)
+	return r.queryDocuments(ctx, query, subject.ID)
+}
+
+func (r *DocumentRepository) Get(ctx context.Context, subject document.Subject, documentID int64) (*document.Document, error) {
+	column, _, err := documentSubjectColumn(subject)
+	if err != nil {
+		return nil, err
+	}
+	query := fmt.Sprintf(`SELECT %s FROM documents WHERE id = $1 AND %s = $2`, documentColumns, column)
+	var d document.Document
+	if err := scanDocument(r.db.QueryRow(ctx, query, documentID, subject.ID), &d); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%w: document %d of %s", apperrors.ErrNotFound, documentID, subject)
+		}
+		r.logger.ErrorContext(ctx, "Failed to get document", slog.Int64("documentID", documentID), slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to get document: %w", apperrors.ErrDatabase, err)
+	}
+	return &d, nil
+}
+
+func (r *DocumentRepository) MarkAvailable(ctx context.Context, subject document.Subject, documentID int64) error {
+	column, _, err := documentSubjectColumn(subject)
+	if err != nil {
+		return err
+	}
+	query := fmt.Sprintf(`UPDATE documents SET status = 'AVAILABLE' WHERE id = $1 AND %s = $2 AND status = 'PENDING'`, column)
+	tag, err := r.db.Exec(ctx, query, documentID, subject.ID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to mark document available", slog.Int64("documentID", documentID), slog.Any("error", err))
+		return fmt.Errorf("%w: failed to update document: %w", apperrors.ErrDatabase, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: pending document %d of %s", apperrors.ErrNotFound, documentID, subject)
+	}
+	return nil
+}
+
+func (r *DocumentRepository) Delete(ctx context.Context, subject document.Subject, documentID int64) error {
+	column, _, err := documentSubjectColumn(subject)
+	if err != nil {
+		return err
+	}
+	tag, err := r.db.Exec(ctx, fmt.Sprintf(`DELETE FROM documents WHERE id = $1 AND %s = $2`, column), documentID, subject.ID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to delete document", slog.Int64("documentID", documentID), slog.Any("error", err))
+		return fmt.Errorf("%w: failed to delete document: %w", apperrors.ErrDatabase, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: document %d of %s", apperrors.ErrNotFound, documentID, subject)
+	}
+	return nil
+}
+
+func (r *DocumentRepository) ListExpired(ctx context.Context, t document.Type, before time.Time, limit int) ([]document.Document, error) {
+	return r.queryDocuments(ctx, listExpiredDocumentsQuery, t, before, limit)
+}
+
+func (r *DocumentRepository) ListStalePending(ctx context.Context, before time.Time, limit int) ([]document.Document, error) {
+	return r.queryDocuments(ctx, listStalePendingDocumentsQuery, before, limit)
+}
+
+func (r *DocumentRepository) SubjectExists(ctx context.Context, subject document.Subject) (bool, error) {
+	_, table, err := documentSubjectColumn(subject)
+	if err != nil {
+		return false, err
+	}
+	var exists bool
+	query := fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM %s WHERE id = $1)`, table)
+	if err := r.db.QueryRow(ctx, query, subject.ID).Scan(&exists); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to check subject", slog.String("subject", subject.String()), slog.Any("error", err))
+		return false, fmt.Errorf("%w: failed to check %s: %w", apperrors.ErrDatabase, subject.Type, err)
+	}
+	return exists, nil
+}
+
+func (r *DocumentRepository) queryDocuments(ctx context.Context, query string, args ...any) ([]document.Document, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query documents", slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to list documents: %w", apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	documents := []document.Document{}
+	for rows.Next() {
+		var d document.Document
+		if err := scanDocument(rows, &d); err != nil {
+			return nil, fmt.Errorf("%w: failed to scan document: %w", apperrors.ErrDatabase, err)
+		}
+		documents = append(documents, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: failed to iterate documents: %w", apperrors.ErrDatabase, err)
+	}
+	return documents, nil
+}
+
+// scanDocument reads a row of documentColumns. Exactly one of loan_id and
+// customer_id is set, which names the subject.
+func scanDocument(row pgx.Row, d *document.Document) error {
+	var loanID, customerID *int64
+	if err := row.Scan(&d.ID, &loanID, &customerID, &d.Type, &d.Status, &d.FileName, &d.ContentType, &d.SizeBytes, &d.StorageKey, &d.UploadedBy, &d.CreatedAt); err != nil {
+		return err
+	}
+	switch {
+	case loanID != nil:
+		d.Subject = document.LoanSubject(*loanID)
+	case customerID != nil:
+		d.Subject = document.Subject{Type: document.SubjectCustomer, ID: *customerID}
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package postgres
+
+import (
+	"billing-engine/internal/domain/document"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var documentRowColumns = []string{"id", "loan_id", "customer_id", "type", "status", "file_name", "content_type", "size_bytes", "storage_key", "uploaded_by", "created_at"}
+
+func setupDocumentRepo(t *testing.T) (context.Context, *DocumentRepository, pgxmock.PgxPoolIface) {
+	t.Helper()
+	mockPool, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("failed to open a stub database connection: %v", err)
+	}
+	return context.Background(), NewDocumentRepository(mockPool, testClock, logger), mockPool
+}
+
+func TestDocumentRepositoryListUncertified(t *testing.T) {
+	ctx, repo, mockPool := setupDocumentRepo(t)
+	defer mockPool.Close()
+
+	since := testClock.Now().Add(-30 * 24 * time.Hour)
+	publicID := uuid.New()
+	customerID := int64(9)
+	paidOffAt := testClock.Now().Add(-time.Hour)
+	mockPool.ExpectQuery(`WHERE l.status = 'PAID_OFF'\s+AND NOT EXISTS \(SELECT 1 FROM documents d`).
+		WithArgs(since, 100).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "public_id", "external_ref", "customer_id", "name", "principal_amount", "total_loan_amount", "start_date", "completed_at"}).
+			AddRow(int64(42), publicID, "EXT-42", &customerID, "Budi", 5000000.0, 5500000.0, paidOffAt.AddDate(0, -6, 0), paidOffAt).
+			AddRow(int64(43), uuid.New(), "", (*int64)(nil), "", 1000000.0, 1100000.0, paidOffAt, paidOffAt))
+
+	loans, err := repo.ListUncertified(ctx, since, 100)
+
+	require.NoError(t, err)
+	require.Len(t, loans, 2)
+	assert.Equal(t, publicID, loans[0].PublicID)
+	assert.Equal(t, &customerID, loans[0].CustomerID)
+	assert.Equal(t, 5500000.0, loans[0].TotalRepaid)
+	assert.Nil(t, loans[1].CustomerID)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestDocumentRepositoryCreate(t *testing.T) {
+	ctx, repo, mockPool := setupDocumentRepo(t)
+	defer mockPool.Close()
+
+	mockPool.ExpectQuery(`INSERT INTO documents \(customer_id, type, status, file_name, content_type, size_bytes, storage_key, uploaded_by, created_at\)`).
+		WithArgs(int64(7), document.TypeIDCopy, document.StatusPending, "ktp.png", "image/png", int64(1024), "customers/7/documents/key.png", "agent", testClock.Now()).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "created_at"}).AddRow(int64(5), testClock.Now()))
+
+	d := &document.Document{Subject: document.Subject{Type: document.SubjectCustomer, ID: 7}, Type: document.TypeIDCopy, Status: document.StatusPending,
+		FileName: "ktp.png", ContentType: "image/png", SizeBytes: 1024, StorageKey: "customers/7/documents/key.png", UploadedBy: "agent"}
+	err := repo.Create(ctx, d)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), d.ID)
+	assert.Equal(t, testClock.Now(), d.CreatedAt)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestDocumentRepositoryCreateConstraintViolations(t *testing.T) {
+	tests := []struct {
+		code string
+		want error
+	}{
+		{code: "23503", want: apperrors.ErrNotFound},
+		{code: "23505", want: apperrors.ErrConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			ctx, repo, mockPool := setupDocumentRepo(t)
+			defer mockPool.Close()
+
+			mockPool.ExpectQuery(`INSERT INTO documents \(loan_id,`).
+				WithArgs(int64(42), document.TypePayoffCertificate, document.StatusAvailable, "f.pdf", document.ContentTypePDF, int64(1), "k", "", testClock.Now()).
+				WillReturnError(&pgconn.PgError{Code: tt.code})
+
+			err := repo.Create(ctx, &document.Document{Subject: document.LoanSubject(42), Type: document.TypePayoffCertificate, Status: document.StatusAvailable,
+				FileName: "f.pdf", ContentType: document.ContentTypePDF, SizeBytes: 1, StorageKey: "k"})
+
+			assert.ErrorIs(t, err, tt.want)
+			assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+		})
+	}
+}
+
+func TestDocumentRepositoryList(t *testing.T) {
+	ctx, repo, mockPool := setupDocumentRepo(t)
+	defer mockPool.Close()
+
+	now := time.Now()
+	customerID := int64(7)
+	mockPool.ExpectQuery(`FROM documents WHERE customer_id = \$1 ORDER BY created_at DESC, id DESC`).
+		WithArgs(int64(7)).
+		WillReturnRows(pgxmock.NewRows(documentRowColumns).
+			AddRow(int64(5), (*int64)(nil), &customerID, document.TypeIDCopy, document.StatusAvailable, "ktp.png", "image/png", int64(1024), "k", "agent", now))
+
+	documents, err := repo.List(ctx, document.Subject{Type: document.SubjectCustomer, ID: 7})
+
+	require.NoError(t, err)
+	require.Len(t, documents, 1)
+	assert.Equal(t, document.Subject{Type: document.SubjectCustomer, ID: 7}, documents[0].Subject)
+	assert.Equal(t, document.TypeIDCopy, documents[0].Type)
+	assert.Equal(t, "k", documents[0].StorageKey)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestDocumentRepositoryGetNotFound(t *testing.T) {
+	ctx, repo, mockPool := setupDocumentRepo(t)
+	defer mockPool.Close()
+
+	mockPool.ExpectQuery(`FROM documents WHERE id = \$1 AND loan_id = \$2`).
+		WithArgs(int64(5), int64(42)).
+		WillReturnRows(pgxmock.NewRows(documentRowColumns))
+
+	d, err := repo.Get(ctx, document.LoanSubject(42), 5)
+
+	assert.Nil(t, d)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestDocumentRepositoryMarkAvailable(t *testing.T) {
+	ctx, repo, mockPool := setupDocumentRepo(t)
+	defer mockPool.Close()
+
+	mockPool.ExpectExec(`UPDATE documents SET status = 'AVAILABLE' WHERE id = \$1 AND loan_id = \$2 AND status = 'PENDING'`).
+		WithArgs(int64(5), int64(42)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mockPool.ExpectExec(`UPDATE documents SET status = 'AVAILABLE'`).
+		WithArgs(int64(6), int64(42)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	require.NoError(t, repo.MarkAvailable(ctx, document.LoanSubject(42), 5))
+	assert.ErrorIs(t, repo.MarkAvailable(ctx, document.LoanSubject(42), 6), apperrors.ErrNotFound)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestDocumentRepositoryListExpired(t *testing.T) {
+	ctx, repo, mockPool := setupDocumentRepo(t)
+	defer mockPool.Close()
+
+	before := testClock.Now().AddDate(-1, 0, 0)
+	loanID := int64(42)
+	mockPool.ExpectQuery(`WHERE d.type = \$1 AND d.status = 'AVAILABLE' AND d.created_at < \$2\s+AND NOT EXISTS \(SELECT 1 FROM loan_holds h`).
+		WithArgs(document.TypeContract, before, 100).
+		WillReturnRows(pgxmock.NewRows(documentRowColumns).
+			AddRow(int64(5), &loanID, (*int64)(nil), document.TypeContract, document.StatusAvailable, "c.pdf", document.ContentTypePDF, int64(1), "k", "", before))
+
+	documents, err := repo.ListExpired(ctx, document.TypeContract, before, 100)
+
+	require.NoError(t, err)
+	require.Len(t, documents, 1)
+	assert.Equal(t, document.LoanSubject(42), documents[0].Subject)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestDocumentRepositorySubjectExists(t *testing.T) {
+	ctx, repo, mockPool := setupDocumentRepo(t)
+	defer mockPool.Close()
+
+	mockPool.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM customers WHERE id = \$1\)`).
+		WithArgs(int64(7)).
+		WillReturnRows(pgxmock.NewRows([]string{"exists"}).AddRow(true))
+
+	exists, err := repo.SubjectExists(ctx, document.Subject{Type: document.SubjectCustomer, ID: 7})
+
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
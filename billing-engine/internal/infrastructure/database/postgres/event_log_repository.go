@@ -0,0 +1,186 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"billing-engine/internal/event"
+	"billing-engine/internal/infrastructure/monitoring"
+	"billing-engine/internal/pkg/apperrors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// nextEventSequenceQuery takes the customer's row lock for the rest of the
+// transaction, so concurrent callers get consecutive numbers and log their
+// events in that order.
+const nextEventSequenceQuery = `
+        INSERT INTO event_sequences (entity_id, last_sequence) VALUES ($1, 1)
+        ON CONFLICT (entity_id) DO UPDATE SET last_sequence = event_sequences.last_sequence + 1
+        RETURNING last_sequence`
+
+const appendEventQuery = `
+        INSERT INTO event_log (event_id, event_type, entity_id, sequence, payload, occurred_at)
+        VALUES ($1, $2, $3, NULLIF($4, 0), $5, $6)
+        RETURNING id`
+
+const markEventPublishedQuery = `
+        UPDATE event_log SET published_at = $2 WHERE event_id = $1 AND published_at IS NULL`
+
+// listEventsQuery treats a NULL or empty argument as "any".
+const listEventsQuery = `
+        SELECT id, event_id, event_type, entity_id, COALESCE(sequence, 0), payload, occurred_at, published_at, replay_count, last_replayed_at
+        FROM event_log
+        WHERE ($1::bigint IS NULL OR entity_id = $1)
+          AND (cardinality($2::text[]) = 0 OR event_type = ANY($2))
+          AND ($3::timestamptz IS NULL OR occurred_at >= $3)
+          AND ($4::timestamptz IS NULL OR occurred_at < $4)
+          AND (NOT $5 OR published_at IS NULL)
+        ORDER BY id
+        LIMIT $6`
+
+const countUnpublishedEventsQuery = `SELECT count(*) FROM event_log WHERE published_at IS NULL`
+
+const markEventReplayedQuery = `
+        UPDATE event_log
+        SET replay_count = replay_count + 1, last_replayed_at = $2, published_at = COALESCE(published_at, $2)
+        WHERE id = $1`
+
+type EventLogRepository struct {
+	db     DBPool
+	logger *slog.Logger
+}
+
+var _ event.Store = (*EventLogRepository)(nil)
+
+func NewEventLogRepository(db DBPool, logger *slog.Logger) *EventLogRepository {
+	if db == nil {
+		panic("DBPool cannot be nil for EventLogRepository")
+	}
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+		logger.Warn("Warning: No logger provided to NewEventLogRepository, using default stderr handler")
+	}
+	return &EventLogRepository{db: db, logger: logger.With("component", "EventLogRepository")}
+}
+
+func (r *EventLogRepository) Append(ctx context.Context, rec *event.Record) error {
+	start := time.Now()
+	err := r.db.QueryRow(ctx, appendEventQuery, rec.EventID, rec.Type, rec.EntityID, rec.Sequence, []byte(rec.Payload), rec.OccurredAt).Scan(&rec.ID)
+	if err != nil {
+		monitoring.RecordDBQuery("AppendEvent", "error", time.Since(start))
+		return r.appendError(ctx, rec, err)
+	}
+	monitoring.RecordDBQuery("AppendEvent", "success", time.Since(start))
+	return nil
+}
+
+// AppendNumbered keeps the customer's event_sequences row locked, from the
+// upsert that numbers the event until the commit that logs it.
+func (r *EventLogRepository) AppendNumbered(ctx context.Context, rec *event.Record, encode func(seq int64) (json.RawMessage, error)) error {
+	start := time.Now()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to begin transaction", slog.Any("error", err))
+		return fmt.Errorf("%w: failed to begin transaction: %w", apperrors.ErrDatabase, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var seq int64
+	if err := tx.QueryRow(ctx, nextEventSequenceQuery, rec.EntityID).Scan(&seq); err != nil {
+		monitoring.RecordDBQuery("AppendNumberedEvent", "error", time.Since(start))
+		r.logger.ErrorContext(ctx, "Failed to number event", slog.Int64("entityId", rec.EntityID), slog.Any("error", err))
+		return fmt.Errorf("%w: failed to number event: %w", apperrors.ErrDatabase, err)
+	}
+	payload, err := encode(seq)
+	if err != nil {
+		return fmt.Errorf("failed to encode event %s: %w", rec.EventID, err)
+	}
+	var id int64
+	if err := tx.QueryRow(ctx, appendEventQuery, rec.EventID, rec.Type, rec.EntityID, seq, []byte(payload), rec.OccurredAt).Scan(&id); err != nil {
+		monitoring.RecordDBQuery("AppendNumberedEvent", "error", time.Since(start))
+		return r.appendError(ctx, rec, err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		monitoring.RecordDBQuery("AppendNumberedEvent", "error", time.Since(start))
+		return fmt.Errorf("%w: failed to commit event: %w", apperrors.ErrDatabase, err)
+	}
+	monitoring.RecordDBQuery("AppendNumberedEvent", "success", time.Since(start))
+	rec.ID, rec.Sequence, rec.Payload = id, seq, payload
+	return nil
+}
+
+func (r *EventLogRepository) appendError(ctx context.Context, rec *event.Record, err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return fmt.Errorf("%w: event %s is already recorded", apperrors.ErrAlreadyExists, rec.EventID)
+	}
+	r.logger.ErrorContext(ctx, "Failed to record event", slog.String("eventId", rec.EventID), slog.Any("error", err))
+	return fmt.Errorf("%w: failed to record event: %w", apperrors.ErrDatabase, err)
+}
+
+func (r *EventLogRepository) MarkPublished(ctx context.Context, eventID string, at time.Time) error {
+	if _, err := r.db.Exec(ctx, markEventPublishedQuery, eventID, at); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to mark event published", slog.String("eventId", eventID), slog.Any("error", err))
+		return fmt.Errorf("%w: failed to mark event published: %w", apperrors.ErrDatabase, err)
+	}
+	return nil
+}
+
+func (r *EventLogRepository) List(ctx context.Context, f event.ReplayFilter) ([]event.Record, error) {
+	start := time.Now()
+	types := f.Types
+	if types == nil {
+		types = []string{}
+	}
+	rows, err := r.db.Query(ctx, listEventsQuery, f.EntityID, types, f.Since, f.Until, f.UnpublishedOnly, f.Limit)
+	if err != nil {
+		monitoring.RecordDBQuery("ListEvents", "error", time.Since(start))
+		r.logger.ErrorContext(ctx, "Failed to query event log", slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to list events: %w", apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	records := []event.Record{}
+	for rows.Next() {
+		var rec event.Record
+		var payload []byte
+		if err := rows.Scan(&rec.ID, &rec.EventID, &rec.Type, &rec.EntityID, &rec.Sequence, &payload, &rec.OccurredAt, &rec.PublishedAt,
+			&rec.ReplayCount, &rec.LastReplayedAt); err != nil {
+			r.logger.ErrorContext(ctx, "Failed to scan event log row", slog.Any("error", err))
+			return nil, fmt.Errorf("%w: failed to scan event: %w", apperrors.ErrDatabase, err)
+		}
+		rec.Payload = payload
+		records = append(records, rec)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.ErrorContext(ctx, "Error iterating event log rows", slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to iterate events: %w", apperrors.ErrDatabase, err)
+	}
+
+	monitoring.RecordDBQuery("ListEvents", "success", time.Since(start))
+	return records, nil
+}
+
+func (r *EventLogRepository) MarkReplayed(ctx context.Context, id int64, at time.Time) error {
+	if _, err := r.db.Exec(ctx, markEventReplayedQuery, id, at); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to record event replay", slog.Int64("id", id), slog.Any("error", err))
+		return fmt.Errorf("%w: failed to record event replay: %w", apperrors.ErrDatabase, err)
+	}
+	return nil
+}
+
+// CountUnpublished is served by the partial index on unpublished events.
+func (r *EventLogRepository) CountUnpublished(ctx context.Context) (int64, error) {
+	var n int64
+	if err := r.db.QueryRow(ctx, countUnpublishedEventsQuery).Scan(&n); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to count unpublished events", slog.Any("error", err))
+		return 0, fmt.Errorf("%w: failed to count unpublished events: %w", apperrors.ErrDatabase, err)
+	}
+	return n, nil
+}
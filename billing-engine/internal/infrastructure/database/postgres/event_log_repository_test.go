@@ -0,0 +1,187 @@
+package postgres
+
+import (
+	"billing-engine/internal/event"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupEventLogRepo(t *testing.T) (context.Context, *EventLogRepository, pgxmock.PgxPoolIface) {
+	t.Helper()
+	mockPool, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("failed to open a stub database connection: %v", err)
+	}
+	return context.Background(), NewEventLogRepository(mockPool, logger), mockPool
+}
+
+func TestEventLogRepositoryAppendNumbered(t *testing.T) {
+	newRecord := func() *event.Record {
+		return &event.Record{EventID: "e-1", Type: event.TypeCustomerUpdated, EntityID: 7, OccurredAt: testClock.Now()}
+	}
+	encode := func(seq int64) (json.RawMessage, error) {
+		return json.Marshal(map[string]any{"eventId": "e-1", "sequence": seq})
+	}
+
+	t.Run("numbers and logs the event in one transaction", func(t *testing.T) {
+		ctx, repo, mockPool := setupEventLogRepo(t)
+		defer mockPool.Close()
+
+		mockPool.ExpectBegin()
+		mockPool.ExpectQuery(regexp.QuoteMeta(nextEventSequenceQuery)).
+			WithArgs(int64(7)).
+			WillReturnRows(pgxmock.NewRows([]string{"last_sequence"}).AddRow(int64(3)))
+		mockPool.ExpectQuery(regexp.QuoteMeta(appendEventQuery)).
+			WithArgs("e-1", event.TypeCustomerUpdated, int64(7), int64(3), []byte(`{"eventId":"e-1","sequence":3}`), testClock.Now()).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int64(4)))
+		mockPool.ExpectCommit()
+
+		rec := newRecord()
+		require.NoError(t, repo.AppendNumbered(ctx, rec, encode))
+		assert.Equal(t, int64(4), rec.ID)
+		assert.Equal(t, int64(3), rec.Sequence)
+		assert.JSONEq(t, `{"eventId":"e-1","sequence":3}`, string(rec.Payload))
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("a failed append gives the number back", func(t *testing.T) {
+		ctx, repo, mockPool := setupEventLogRepo(t)
+		defer mockPool.Close()
+
+		mockPool.ExpectBegin()
+		mockPool.ExpectQuery(regexp.QuoteMeta(nextEventSequenceQuery)).
+			WithArgs(int64(7)).
+			WillReturnRows(pgxmock.NewRows([]string{"last_sequence"}).AddRow(int64(3)))
+		mockPool.ExpectQuery(regexp.QuoteMeta(appendEventQuery)).
+			WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnError(&pgconn.PgError{Code: "23505"})
+		mockPool.ExpectRollback()
+
+		rec := newRecord()
+		assert.ErrorIs(t, repo.AppendNumbered(ctx, rec, encode), apperrors.ErrAlreadyExists)
+		assert.Zero(t, rec.Sequence)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("numbering fails", func(t *testing.T) {
+		ctx, repo, mockPool := setupEventLogRepo(t)
+		defer mockPool.Close()
+
+		mockPool.ExpectBegin()
+		mockPool.ExpectQuery(regexp.QuoteMeta(nextEventSequenceQuery)).
+			WithArgs(int64(7)).
+			WillReturnError(errors.New("connection reset"))
+		mockPool.ExpectRollback()
+
+		assert.ErrorIs(t, repo.AppendNumbered(ctx, newRecord(), encode), apperrors.ErrDatabase)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+}
+
+func TestEventLogRepositoryAppend(t *testing.T) {
+	newRecord := func() *event.Record {
+		return &event.Record{EventID: "e-1", Type: event.TypeCustomerCreated, EntityID: 7, Sequence: 2,
+			Payload: json.RawMessage(`{"eventId":"e-1"}`), OccurredAt: testClock.Now()}
+	}
+
+	t.Run("inserts the event", func(t *testing.T) {
+		ctx, repo, mockPool := setupEventLogRepo(t)
+		defer mockPool.Close()
+
+		mockPool.ExpectQuery(regexp.QuoteMeta(appendEventQuery)).
+			WithArgs("e-1", event.TypeCustomerCreated, int64(7), int64(2), []byte(`{"eventId":"e-1"}`), testClock.Now()).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int64(4)))
+
+		rec := newRecord()
+		require.NoError(t, repo.Append(ctx, rec))
+		assert.Equal(t, int64(4), rec.ID)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("event already recorded", func(t *testing.T) {
+		ctx, repo, mockPool := setupEventLogRepo(t)
+		defer mockPool.Close()
+
+		mockPool.ExpectQuery(regexp.QuoteMeta(appendEventQuery)).
+			WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnError(&pgconn.PgError{Code: "23505"})
+
+		assert.ErrorIs(t, repo.Append(ctx, newRecord()), apperrors.ErrAlreadyExists)
+	})
+}
+
+func TestEventLogRepositoryList(t *testing.T) {
+	ctx, repo, mockPool := setupEventLogRepo(t)
+	defer mockPool.Close()
+
+	entity := int64(7)
+	since := testClock.Now().AddDate(0, 0, -1)
+	published := testClock.Now()
+	mockPool.ExpectQuery(regexp.QuoteMeta(listEventsQuery)).
+		WithArgs(&entity, []string{}, &since, (*time.Time)(nil), false, 100).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "event_id", "event_type", "entity_id", "sequence", "payload", "occurred_at",
+			"published_at", "replay_count", "last_replayed_at"}).
+			AddRow(int64(1), "e-1", event.TypeCustomerCreated, int64(7), int64(1), []byte(`{"eventId":"e-1"}`), since, &published, 0, (*time.Time)(nil)))
+
+	records, err := repo.List(ctx, event.ReplayFilter{EntityID: &entity, Since: &since, Limit: 100})
+
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, json.RawMessage(`{"eventId":"e-1"}`), records[0].Payload)
+	assert.Equal(t, &published, records[0].PublishedAt)
+	assert.Equal(t, int64(1), records[0].Sequence)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestEventLogRepositoryMarkReplayed(t *testing.T) {
+	t.Run("counts the replay", func(t *testing.T) {
+		ctx, repo, mockPool := setupEventLogRepo(t)
+		defer mockPool.Close()
+
+		mockPool.ExpectExec(regexp.QuoteMeta(markEventReplayedQuery)).
+			WithArgs(int64(4), testClock.Now()).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+		require.NoError(t, repo.MarkReplayed(ctx, 4, testClock.Now()))
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		ctx, repo, mockPool := setupEventLogRepo(t)
+		defer mockPool.Close()
+
+		mockPool.ExpectExec(regexp.QuoteMeta(markEventReplayedQuery)).
+			WithArgs(int64(4), testClock.Now()).
+			WillReturnError(errors.New("connection reset"))
+
+		assert.ErrorIs(t, repo.MarkReplayed(ctx, 4, testClock.Now()), apperrors.ErrDatabase)
+	})
+}
+
+func TestEventLogRepositoryCountUnpublished(t *testing.T) {
+	ctx, repo, mockPool := setupEventLogRepo(t)
+	defer mockPool.Close()
+
+	mockPool.ExpectQuery(regexp.QuoteMeta(countUnpublishedEventsQuery)).
+		WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(int64(3)))
+	n, err := repo.CountUnpublished(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+
+	mockPool.ExpectQuery(regexp.QuoteMeta(countUnpublishedEventsQuery)).
+		WillReturnError(errors.New("connection reset"))
+	_, err = repo.CountUnpublished(ctx)
+	assert.ErrorIs(t, err, apperrors.ErrDatabase)
+
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
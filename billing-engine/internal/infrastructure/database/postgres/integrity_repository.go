@@ -0,0 +1,191 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"billing-engine/internal/domain/integrity"
+	"billing-engine/internal/infrastructure/monitoring"
+	"billing-engine/internal/pkg/apperrors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	detectCustomerLoanMissingQuery = `
+        SELECT c.id, c.loan_id
+        FROM customers c
+        LEFT JOIN loans l ON l.id = c.loan_id
+        WHERE c.loan_id IS NOT NULL AND l.id IS NULL
+        ORDER BY c.id`
+
+	// detectLedgerMismatchQuery compares what the schedule says was paid on
+	// each loan with the installment payments in the ledger, allowing for
+	// rounding to cents. Payments of fees are not part of the schedule.
+	detectLedgerMismatchQuery = `
+        SELECT s.loan_id, s.paid, COALESCE(p.paid, 0)
+        FROM (SELECT loan_id, SUM(paid_amount) AS paid FROM loan_schedule GROUP BY loan_id) s
+        LEFT JOIN (SELECT loan_id, SUM(amount) AS paid FROM payments WHERE schedule_id IS NOT NULL GROUP BY loan_id) p ON p.loan_id = s.loan_id
+        WHERE ABS(s.paid - COALESCE(p.paid, 0)) > 0.005
+        ORDER BY s.loan_id`
+
+	detectPaidOffUnpaidQuery = `
+        SELECT l.id, COUNT(*)
+        FROM loans l
+        JOIN loan_schedule s ON s.loan_id = l.id
+        WHERE l.status = 'PAID_OFF' AND s.status != 'PAID'
+        GROUP BY l.id
+        ORDER BY l.id`
+
+	detectOrphanScheduleQuery = `
+        SELECT s.loan_id, COUNT(*)
+        FROM loan_schedule s
+        LEFT JOIN loans l ON l.id = s.loan_id
+        WHERE l.id IS NULL
+        GROUP BY s.loan_id
+        ORDER BY s.loan_id`
+
+	upsertIntegrityFindingQuery = `
+        INSERT INTO integrity_findings (check_name, entity_type, entity_id, detail, first_seen_at, last_seen_at)
+        VALUES ($1, $2, $3, $4, $5, $5)
+        ON CONFLICT (check_name, entity_id) DO UPDATE SET
+            entity_type = EXCLUDED.entity_type, detail = EXCLUDED.detail, last_seen_at = EXCLUDED.last_seen_at`
+
+	deleteStaleIntegrityFindingsQuery = `DELETE FROM integrity_findings WHERE last_seen_at < $1`
+
+	listIntegrityFindingsQuery = `
+        SELECT id, check_name, entity_type, entity_id, detail, first_seen_at, last_seen_at
+        FROM integrity_findings
+        WHERE $1 = '' OR check_name = $1
+        ORDER BY first_seen_at, id`
+)
+
+// integrityDetector is the query of a check and how a row of it becomes a
+// finding.
+type integrityDetector struct {
+	query string
+	scan  func(row pgx.Rows) (integrity.Finding, error)
+}
+
+var integrityDetectors = map[integrity.Check]integrityDetector{
+	integrity.CheckCustomerLoanMissing: {detectCustomerLoanMissingQuery, func(row pgx.Rows) (integrity.Finding, error) {
+		var customerID, loanID int64
+		err := row.Scan(&customerID, &loanID)
+		return integrity.CustomerLoanMissing(customerID, loanID), err
+	}},
+	integrity.CheckLedgerMismatch: {detectLedgerMismatchQuery, func(row pgx.Rows) (integrity.Finding, error) {
+		var loanID int64
+		var schedulePaid, ledgerPaid float64
+		err := row.Scan(&loanID, &schedulePaid, &ledgerPaid)
+		return integrity.LedgerMismatch(loanID, schedulePaid, ledgerPaid), err
+	}},
+	integrity.CheckPaidOffUnpaid: {detectPaidOffUnpaidQuery, func(row pgx.Rows) (integrity.Finding, error) {
+		var loanID int64
+		var unpaid int
+		err := row.Scan(&loanID, &unpaid)
+		return integrity.PaidOffUnpaid(loanID, unpaid), err
+	}},
+	integrity.CheckOrphanSchedule: {detectOrphanScheduleQuery, func(row pgx.Rows) (integrity.Finding, error) {
+		var loanID int64
+		var entries int
+		err := row.Scan(&loanID, &entries)
+		return integrity.OrphanSchedule(loanID, entries), err
+	}},
+}
+
+type IntegrityRepository struct {
+	db     DBPool
+	logger *slog.Logger
+}
+
+var _ integrity.Repository = (*IntegrityRepository)(nil)
+
+func NewIntegrityRepository(db DBPool, logger *slog.Logger) *IntegrityRepository {
+	if db == nil {
+		panic("DBPool cannot be nil for IntegrityRepository")
+	}
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+		logger.Warn("Warning: No logger provided to NewIntegrityRepository, using default stderr handler")
+	}
+	return &IntegrityRepository{db: db, logger: logger.With("component", "IntegrityRepository")}
+}
+
+func (r *IntegrityRepository) Detect(ctx context.Context, check integrity.Check) ([]integrity.Finding, error) {
+	detector, ok := integrityDetectors[check]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown integrity check %q", apperrors.ErrInvalidArgument, check)
+	}
+
+	start := time.Now()
+	rows, err := r.db.Query(ctx, detector.query)
+	if err != nil {
+		monitoring.RecordDBQuery("IntegrityCheck", "error", time.Since(start))
+		r.logger.ErrorContext(ctx, "Failed to run integrity check", slog.String("check", string(check)), slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to run integrity check %s: %w", apperrors.ErrDatabase, check, err)
+	}
+	defer rows.Close()
+
+	findings := []integrity.Finding{}
+	for rows.Next() {
+		f, err := detector.scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to scan integrity check %s: %w", apperrors.ErrDatabase, check, err)
+		}
+		findings = append(findings, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: failed to iterate integrity check %s: %w", apperrors.ErrDatabase, check, err)
+	}
+	monitoring.RecordDBQuery("IntegrityCheck", "success", time.Since(start))
+	return findings, nil
+}
+
+func (r *IntegrityRepository) ReplaceFindings(ctx context.Context, runAt time.Time, findings []integrity.Finding) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to begin transaction", slog.Any("error", err))
+		return fmt.Errorf("%w: failed to begin transaction: %w", apperrors.ErrDatabase, err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	for _, f := range findings {
+		if _, err := tx.Exec(ctx, upsertIntegrityFindingQuery, f.Check, f.EntityType, f.EntityID, f.Detail, runAt); err != nil {
+			r.logger.ErrorContext(ctx, "Failed to store integrity finding", slog.String("check", string(f.Check)), slog.Int64("entityID", f.EntityID), slog.Any("error", err))
+			return fmt.Errorf("%w: failed to store integrity finding: %w", apperrors.ErrDatabase, err)
+		}
+	}
+	if _, err := tx.Exec(ctx, deleteStaleIntegrityFindingsQuery, runAt); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to delete resolved integrity findings", slog.Any("error", err))
+		return fmt.Errorf("%w: failed to delete resolved integrity findings: %w", apperrors.ErrDatabase, err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%w: failed to commit integrity findings: %w", apperrors.ErrDatabase, err)
+	}
+	return nil
+}
+
+func (r *IntegrityRepository) ListFindings(ctx context.Context, check integrity.Check) ([]integrity.Finding, error) {
+	rows, err := r.db.Query(ctx, listIntegrityFindingsQuery, check)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query integrity findings", slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to list integrity findings: %w", apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	findings := []integrity.Finding{}
+	for rows.Next() {
+		var f integrity.Finding
+		if err := rows.Scan(&f.ID, &f.Check, &f.EntityType, &f.EntityID, &f.Detail, &f.FirstSeenAt, &f.LastSeenAt); err != nil {
+			return nil, fmt.Errorf("%w: failed to scan integrity finding: %w", apperrors.ErrDatabase, err)
+		}
+		findings = append(findings, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: failed to iterate integrity findings: %w", apperrors.ErrDatabase, err)
+	}
+	return findings, nil
+}
@@ -0,0 +1,119 @@
+package postgres
+
+import (
+	"billing-engine/internal/domain/integrity"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupIntegrityRepo(t *testing.T) (context.Context, *IntegrityRepository, pgxmock.PgxPoolIface) {
+	t.Helper()
+	mockPool, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("failed to open a stub database connection: %v", err)
+	}
+	return context.Background(), NewIntegrityRepository(mockPool, logger), mockPool
+}
+
+func TestIntegrityRepositoryDetect(t *testing.T) {
+	t.Run("turns rows into findings", func(t *testing.T) {
+		ctx, repo, mockPool := setupIntegrityRepo(t)
+		defer mockPool.Close()
+		mockPool.ExpectQuery(regexp.QuoteMeta(detectLedgerMismatchQuery)).
+			WillReturnRows(pgxmock.NewRows([]string{"loan_id", "schedule_paid", "ledger_paid"}).AddRow(int64(7), 220.0, 110.0))
+
+		findings, err := repo.Detect(ctx, integrity.CheckLedgerMismatch)
+
+		require.NoError(t, err)
+		require.Len(t, findings, 1)
+		assert.Equal(t, integrity.LedgerMismatch(7, 220, 110), findings[0])
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("every check has a query", func(t *testing.T) {
+		for _, check := range integrity.Checks {
+			assert.Contains(t, integrityDetectors, check)
+		}
+	})
+
+	t.Run("unknown check", func(t *testing.T) {
+		ctx, repo, mockPool := setupIntegrityRepo(t)
+		defer mockPool.Close()
+
+		_, err := repo.Detect(ctx, integrity.Check("nope"))
+
+		assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+	})
+
+	t.Run("wraps a failed query", func(t *testing.T) {
+		ctx, repo, mockPool := setupIntegrityRepo(t)
+		defer mockPool.Close()
+		mockPool.ExpectQuery(regexp.QuoteMeta(detectOrphanScheduleQuery)).WillReturnError(errors.New("connection reset"))
+
+		_, err := repo.Detect(ctx, integrity.CheckOrphanSchedule)
+
+		assert.ErrorIs(t, err, apperrors.ErrDatabase)
+	})
+}
+
+func TestIntegrityRepositoryReplaceFindings(t *testing.T) {
+	runAt := time.Date(2025, 3, 10, 4, 15, 0, 0, time.UTC)
+
+	t.Run("upserts the findings and drops resolved ones", func(t *testing.T) {
+		ctx, repo, mockPool := setupIntegrityRepo(t)
+		defer mockPool.Close()
+		f := integrity.PaidOffUnpaid(3, 2)
+		mockPool.ExpectBegin()
+		mockPool.ExpectExec(regexp.QuoteMeta(upsertIntegrityFindingQuery)).
+			WithArgs(integrity.CheckPaidOffUnpaid, integrity.EntityLoan, int64(3), f.Detail, runAt).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mockPool.ExpectExec(regexp.QuoteMeta(deleteStaleIntegrityFindingsQuery)).WithArgs(runAt).
+			WillReturnResult(pgxmock.NewResult("DELETE", 4))
+		mockPool.ExpectCommit()
+
+		err := repo.ReplaceFindings(ctx, runAt, []integrity.Finding{f})
+
+		require.NoError(t, err)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("rolls back when an upsert fails", func(t *testing.T) {
+		ctx, repo, mockPool := setupIntegrityRepo(t)
+		defer mockPool.Close()
+		mockPool.ExpectBegin()
+		mockPool.ExpectExec(regexp.QuoteMeta(upsertIntegrityFindingQuery)).
+			WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnError(errors.New("connection reset"))
+		mockPool.ExpectRollback()
+
+		err := repo.ReplaceFindings(ctx, runAt, []integrity.Finding{integrity.OrphanSchedule(5, 1)})
+
+		assert.ErrorIs(t, err, apperrors.ErrDatabase)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+}
+
+func TestIntegrityRepositoryListFindings(t *testing.T) {
+	ctx, repo, mockPool := setupIntegrityRepo(t)
+	defer mockPool.Close()
+	seen := time.Date(2025, 3, 10, 4, 15, 0, 0, time.UTC)
+	mockPool.ExpectQuery(regexp.QuoteMeta(listIntegrityFindingsQuery)).WithArgs(integrity.CheckCustomerLoanMissing).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "check_name", "entity_type", "entity_id", "detail", "first_seen_at", "last_seen_at"}).
+			AddRow(int64(1), integrity.CheckCustomerLoanMissing, integrity.EntityCustomer, int64(4), "gone", seen, seen))
+
+	findings, err := repo.ListFindings(ctx, integrity.CheckCustomerLoanMissing)
+
+	require.NoError(t, err)
+	require.Len(t, findings, 1)
+	assert.Equal(t, int64(4), findings[0].EntityID)
+	assert.Equal(t, integrity.CheckCustomerLoanMissing, findings[0].Check)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
@@ -0,0 +1,64 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"billing-engine/internal/jobs"
+	"billing-engine/internal/pkg/apperrors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// tryJobLockQuery inserts the lock, or takes it over when it is the
+// owner's already or has lapsed; no row comes back while another owner
+// holds it.
+const tryJobLockQuery = `
+        INSERT INTO job_locks (name, owner, expires_at) VALUES ($1, $2, $4)
+        ON CONFLICT (name) DO UPDATE SET owner = EXCLUDED.owner, expires_at = EXCLUDED.expires_at
+        WHERE job_locks.owner = EXCLUDED.owner OR job_locks.expires_at <= $3
+        RETURNING owner`
+
+const unlockJobQuery = `DELETE FROM job_locks WHERE name = $1 AND owner = $2`
+
+type JobLockRepository struct {
+	db     DBPool
+	logger *slog.Logger
+}
+
+var _ jobs.Locker = (*JobLockRepository)(nil)
+
+func NewJobLockRepository(db DBPool, logger *slog.Logger) *JobLockRepository {
+	if db == nil {
+		panic("DBPool cannot be nil for JobLockRepository")
+	}
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+		logger.Warn("Warning: No logger provided to NewJobLockRepository, using default stderr handler")
+	}
+	return &JobLockRepository{db: db, logger: logger.With("component", "JobLockRepository")}
+}
+
+func (r *JobLockRepository) TryLock(ctx context.Context, name, owner string, now, expires time.Time) (bool, error) {
+	var holder string
+	err := r.db.QueryRow(ctx, tryJobLockQuery, name, owner, now, expires).Scan(&holder)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to take job lock", slog.String("name", name), slog.Any("error", err))
+		return false, fmt.Errorf("%w: failed to take job lock: %w", apperrors.ErrDatabase, err)
+	}
+	return true, nil
+}
+
+func (r *JobLockRepository) Unlock(ctx context.Context, name, owner string) error {
+	if _, err := r.db.Exec(ctx, unlockJobQuery, name, owner); err != nil {
+		return fmt.Errorf("%w: failed to release job lock: %w", apperrors.ErrDatabase, err)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package postgres
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobLockRepositoryTryLock(t *testing.T) {
+	now := time.Date(2025, 1, 6, 2, 0, 0, 0, time.UTC)
+	expires := now.Add(2 * time.Minute)
+
+	tests := []struct {
+		name     string
+		setup    func(mock pgxmock.PgxPoolIface)
+		acquired bool
+		err      error
+	}{
+		{
+			name: "taken",
+			setup: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectQuery(regexp.QuoteMeta(tryJobLockQuery)).WithArgs("DelinquencyUpdate", "job-1", now, expires).
+					WillReturnRows(pgxmock.NewRows([]string{"owner"}).AddRow("job-1"))
+			},
+			acquired: true,
+		},
+		{
+			name: "held by another run",
+			setup: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectQuery(regexp.QuoteMeta(tryJobLockQuery)).WithArgs("DelinquencyUpdate", "job-1", now, expires).
+					WillReturnRows(pgxmock.NewRows([]string{"owner"}))
+			},
+		},
+		{
+			name: "database error",
+			setup: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectQuery(regexp.QuoteMeta(tryJobLockQuery)).WithArgs("DelinquencyUpdate", "job-1", now, expires).
+					WillReturnError(errors.New("connection reset"))
+			},
+			err: apperrors.ErrDatabase,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockPool, err := pgxmock.NewPool()
+			require.NoError(t, err)
+			defer mockPool.Close()
+			tt.setup(mockPool)
+
+			acquired, err := NewJobLockRepository(mockPool, logger).TryLock(context.Background(), "DelinquencyUpdate", "job-1", now, expires)
+
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.acquired, acquired)
+			assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+		})
+	}
+}
+
+func TestJobLockRepositoryUnlock(t *testing.T) {
+	mockPool, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mockPool.Close()
+	mockPool.ExpectExec(regexp.QuoteMeta(unlockJobQuery)).WithArgs("DelinquencyUpdate", "job-1").
+		WillReturnResult(pgxmock.NewResult("DELETE", 1))
+
+	require.NoError(t, NewJobLockRepository(mockPool, logger).Unlock(context.Background(), "DelinquencyUpdate", "job-1"))
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
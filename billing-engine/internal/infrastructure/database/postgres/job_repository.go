@@ -0,0 +1,221 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"billing-engine/internal/infrastructure/monitoring"
+	"billing-engine/internal/jobs"
+	"billing-engine/internal/pkg/apperrors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const jobColumns = `id, kind, status, COALESCE(dedupe_key, ''), payload, result, COALESCE(error, ''), total, processed,
+        attempts, COALESCE(worker, ''), created_at, started_at, heartbeat_at, finished_at`
+
+const createJobQuery = `
+        INSERT INTO jobs (id, kind, status, dedupe_key, payload, total, created_at)
+        VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7)`
+
+// claimJobQuery locks the oldest claimable job and skips the rows other
+// workers hold, so that concurrent claims never return the same job.
+const claimJobQuery = `
+        UPDATE jobs
+        SET status = 'RUNNING', worker = $2, attempts = attempts + 1, started_at = $3, heartbeat_at = $3
+        WHERE id = (
+            SELECT id FROM jobs
+            WHERE kind = ANY($1::text[])
+              AND (status = 'QUEUED' OR (status = 'RUNNING' AND heartbeat_at < $4))
+            ORDER BY created_at
+            LIMIT 1
+            FOR UPDATE SKIP LOCKED)
+        RETURNING ` + jobColumns
+
+const jobProgressQuery = `UPDATE jobs SET processed = $2, heartbeat_at = $3 WHERE id = $1 AND status = 'RUNNING'`
+
+const jobHeartbeatQuery = `UPDATE jobs SET heartbeat_at = $2 WHERE id = $1 AND status = 'RUNNING'`
+
+const finishJobQuery = `
+        UPDATE jobs
+        SET status = $2, result = $3, error = NULLIF($4, ''), processed = $5, finished_at = $6, payload = NULL
+        WHERE id = $1`
+
+const requeueJobQuery = `
+        UPDATE jobs SET status = 'QUEUED', worker = NULL, started_at = NULL, heartbeat_at = NULL
+        WHERE id = $1 AND status = 'RUNNING'`
+
+const getJobQuery = `SELECT ` + jobColumns + ` FROM jobs WHERE id = $1`
+
+// listJobsQuery treats an empty argument as "any".
+const listJobsQuery = `
+        SELECT ` + jobColumns + `
+        FROM jobs
+        WHERE ($1::text = '' OR kind = $1) AND ($2::text = '' OR status = $2)
+        ORDER BY created_at DESC
+        LIMIT $3`
+
+const countQueuedJobsQuery = `SELECT count(*) FROM jobs WHERE status = 'QUEUED'`
+
+const deleteFinishedJobsQuery = `DELETE FROM jobs WHERE finished_at < $1`
+
+// listJobsMax bounds List when the filter sets no limit.
+const listJobsMax = 1000
+
+type JobRepository struct {
+	db     DBPool
+	logger *slog.Logger
+}
+
+var _ jobs.Store = (*JobRepository)(nil)
+
+func NewJobRepository(db DBPool, logger *slog.Logger) *JobRepository {
+	if db == nil {
+		panic("DBPool cannot be nil for JobRepository")
+	}
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+		logger.Warn("Warning: No logger provided to NewJobRepository, using default stderr handler")
+	}
+	return &JobRepository{db: db, logger: logger.With("component", "JobRepository")}
+}
+
+func (r *JobRepository) Create(ctx context.Context, job *jobs.Job) error {
+	_, err := r.db.Exec(ctx, createJobQuery, job.ID, job.Kind, job.Status, job.DedupeKey, nullJSON(job.Payload), job.Total, job.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return fmt.Errorf("%w: job %s is already queued", apperrors.ErrAlreadyExists, job.DedupeKey)
+		}
+		r.logger.ErrorContext(ctx, "Failed to queue job", slog.String("kind", job.Kind), slog.Any("error", err))
+		return fmt.Errorf("%w: failed to queue job: %w", apperrors.ErrDatabase, err)
+	}
+	return nil
+}
+
+func (r *JobRepository) Claim(ctx context.Context, kinds []string, worker string, now, staleBefore time.Time) (*jobs.Job, error) {
+	start := time.Now()
+	job, err := scanJob(r.db.QueryRow(ctx, claimJobQuery, kinds, worker, now, staleBefore))
+	if errors.Is(err, pgx.ErrNoRows) {
+		monitoring.RecordDBQuery("ClaimJob", "success", time.Since(start))
+		return nil, nil
+	}
+	if err != nil {
+		monitoring.RecordDBQuery("ClaimJob", "error", time.Since(start))
+		return nil, fmt.Errorf("%w: failed to claim job: %w", apperrors.ErrDatabase, err)
+	}
+	monitoring.RecordDBQuery("ClaimJob", "success", time.Since(start))
+	return job, nil
+}
+
+func (r *JobRepository) Progress(ctx context.Context, id string, processed int, at time.Time) error {
+	if _, err := r.db.Exec(ctx, jobProgressQuery, id, processed, at); err != nil {
+		return fmt.Errorf("%w: failed to record job progress: %w", apperrors.ErrDatabase, err)
+	}
+	return nil
+}
+
+func (r *JobRepository) Heartbeat(ctx context.Context, id string, at time.Time) error {
+	if _, err := r.db.Exec(ctx, jobHeartbeatQuery, id, at); err != nil {
+		return fmt.Errorf("%w: failed to renew job lease: %w", apperrors.ErrDatabase, err)
+	}
+	return nil
+}
+
+func (r *JobRepository) Finish(ctx context.Context, job *jobs.Job) error {
+	_, err := r.db.Exec(ctx, finishJobQuery, job.ID, job.Status, nullJSON(job.Result), job.Error, job.Processed, job.FinishedAt)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to record finished job", slog.String("jobId", job.ID), slog.Any("error", err))
+		return fmt.Errorf("%w: failed to record finished job: %w", apperrors.ErrDatabase, err)
+	}
+	return nil
+}
+
+func (r *JobRepository) Requeue(ctx context.Context, id string) error {
+	if _, err := r.db.Exec(ctx, requeueJobQuery, id); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to requeue job", slog.String("jobId", id), slog.Any("error", err))
+		return fmt.Errorf("%w: failed to requeue job: %w", apperrors.ErrDatabase, err)
+	}
+	return nil
+}
+
+func (r *JobRepository) Get(ctx context.Context, id string) (*jobs.Job, error) {
+	job, err := scanJob(r.db.QueryRow(ctx, getJobQuery, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: job %s", apperrors.ErrNotFound, id)
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to get job", slog.String("jobId", id), slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to get job: %w", apperrors.ErrDatabase, err)
+	}
+	return job, nil
+}
+
+func (r *JobRepository) List(ctx context.Context, f jobs.Filter) ([]jobs.Job, error) {
+	limit := f.Limit
+	if limit <= 0 {
+		limit = listJobsMax
+	}
+	rows, err := r.db.Query(ctx, listJobsQuery, f.Kind, string(f.Status), limit)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query jobs", slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to list jobs: %w", apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	found := []jobs.Job{}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to scan job: %w", apperrors.ErrDatabase, err)
+		}
+		found = append(found, *job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: failed to iterate jobs: %w", apperrors.ErrDatabase, err)
+	}
+	return found, nil
+}
+
+// CountQueued is served by the partial index on unfinished jobs.
+func (r *JobRepository) CountQueued(ctx context.Context) (int, error) {
+	var n int
+	if err := r.db.QueryRow(ctx, countQueuedJobsQuery).Scan(&n); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to count queued jobs", slog.Any("error", err))
+		return 0, fmt.Errorf("%w: failed to count queued jobs: %w", apperrors.ErrDatabase, err)
+	}
+	return n, nil
+}
+
+func (r *JobRepository) DeleteFinishedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	tag, err := r.db.Exec(ctx, deleteFinishedJobsQuery, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("%w: failed to delete expired jobs: %w", apperrors.ErrDatabase, err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+func scanJob(row pgx.Row) (*jobs.Job, error) {
+	var job jobs.Job
+	var payload, result []byte
+	if err := row.Scan(&job.ID, &job.Kind, &job.Status, &job.DedupeKey, &payload, &result, &job.Error, &job.Total, &job.Processed,
+		&job.Attempts, &job.Worker, &job.CreatedAt, &job.StartedAt, &job.HeartbeatAt, &job.FinishedAt); err != nil {
+		return nil, err
+	}
+	job.Payload, job.Result = payload, result
+	return &job, nil
+}
+
+// nullJSON stores an absent document as NULL rather than as an empty string,
+// which JSONB would refuse.
+func nullJSON(doc []byte) any {
+	if len(doc) == 0 {
+		return nil
+	}
+	return doc
+}
@@ -0,0 +1,157 @@
+package postgres
+
+import (
+	"billing-engine/internal/jobs"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var jobRowColumns = []string{"id", "kind", "status", "dedupe_key", "payload", "result", "error", "total", "processed",
+	"attempts", "worker", "created_at", "started_at", "heartbeat_at", "finished_at"}
+
+func setupJobRepo(t *testing.T) (context.Context, *JobRepository, pgxmock.PgxPoolIface) {
+	t.Helper()
+	mockPool, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("failed to open a stub database connection: %v", err)
+	}
+	return context.Background(), NewJobRepository(mockPool, logger), mockPool
+}
+
+func TestJobRepositoryCreate(t *testing.T) {
+	createdAt := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	job := &jobs.Job{ID: "3f2b9c1e-0000-4000-8000-000000000001", Kind: "customer-import", Status: jobs.StatusQueued,
+		Payload: json.RawMessage(`[{"Line":2}]`), Total: 1, CreatedAt: createdAt}
+
+	t.Run("inserts the job", func(t *testing.T) {
+		ctx, repo, mockPool := setupJobRepo(t)
+		defer mockPool.Close()
+		mockPool.ExpectExec(regexp.QuoteMeta(createJobQuery)).
+			WithArgs(job.ID, "customer-import", jobs.StatusQueued, "", []byte(job.Payload), 1, createdAt).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+		require.NoError(t, repo.Create(ctx, job))
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("a taken dedupe key", func(t *testing.T) {
+		ctx, repo, mockPool := setupJobRepo(t)
+		defer mockPool.Close()
+		mockPool.ExpectExec(regexp.QuoteMeta(createJobQuery)).
+			WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnError(&pgconn.PgError{Code: "23505", ConstraintName: "uq_jobs_dedupe_key"})
+
+		assert.ErrorIs(t, repo.Create(ctx, job), apperrors.ErrAlreadyExists)
+	})
+}
+
+func TestJobRepositoryClaim(t *testing.T) {
+	now := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	staleBefore := now.Add(-2 * time.Minute)
+	kinds := []string{"customer-import", "event-replay"}
+
+	t.Run("returns the claimed job", func(t *testing.T) {
+		ctx, repo, mockPool := setupJobRepo(t)
+		defer mockPool.Close()
+		mockPool.ExpectQuery(regexp.QuoteMeta(claimJobQuery)).WithArgs(kinds, "host-1", now, staleBefore).
+			WillReturnRows(pgxmock.NewRows(jobRowColumns).AddRow("job-1", "customer-import", jobs.StatusRunning, "",
+				[]byte(`[{"Line":2}]`), nil, "", 1, 0, 1, "host-1", now.Add(-time.Minute), &now, &now, nil))
+
+		job, err := repo.Claim(ctx, kinds, "host-1", now, staleBefore)
+
+		require.NoError(t, err)
+		require.NotNil(t, job)
+		assert.Equal(t, "job-1", job.ID)
+		assert.Equal(t, jobs.StatusRunning, job.Status)
+		assert.Equal(t, 1, job.Attempts)
+		assert.JSONEq(t, `[{"Line":2}]`, string(job.Payload))
+		assert.Nil(t, job.Result)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("nothing to claim", func(t *testing.T) {
+		ctx, repo, mockPool := setupJobRepo(t)
+		defer mockPool.Close()
+		mockPool.ExpectQuery(regexp.QuoteMeta(claimJobQuery)).WithArgs(kinds, "host-1", now, staleBefore).WillReturnRows(pgxmock.NewRows(jobRowColumns))
+
+		job, err := repo.Claim(ctx, kinds, "host-1", now, staleBefore)
+
+		require.NoError(t, err)
+		assert.Nil(t, job)
+	})
+
+	t.Run("wraps a failed claim", func(t *testing.T) {
+		ctx, repo, mockPool := setupJobRepo(t)
+		defer mockPool.Close()
+		mockPool.ExpectQuery(regexp.QuoteMeta(claimJobQuery)).WithArgs(kinds, "host-1", now, staleBefore).WillReturnError(errors.New("connection reset"))
+
+		_, err := repo.Claim(ctx, kinds, "host-1", now, staleBefore)
+
+		assert.ErrorIs(t, err, apperrors.ErrDatabase)
+	})
+}
+
+func TestJobRepositoryFinish(t *testing.T) {
+	ctx, repo, mockPool := setupJobRepo(t)
+	defer mockPool.Close()
+	finishedAt := time.Date(2025, 1, 6, 9, 5, 0, 0, time.UTC)
+	job := &jobs.Job{ID: "job-1", Status: jobs.StatusFailed, Error: "bank file rejected", Processed: 3, FinishedAt: &finishedAt}
+	mockPool.ExpectExec(regexp.QuoteMeta(finishJobQuery)).
+		WithArgs("job-1", jobs.StatusFailed, nil, "bank file rejected", 3, &finishedAt).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	require.NoError(t, repo.Finish(ctx, job))
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestJobRepositoryGet(t *testing.T) {
+	t.Run("unknown job", func(t *testing.T) {
+		ctx, repo, mockPool := setupJobRepo(t)
+		defer mockPool.Close()
+		mockPool.ExpectQuery(regexp.QuoteMeta(getJobQuery)).WithArgs("job-9").WillReturnRows(pgxmock.NewRows(jobRowColumns))
+
+		_, err := repo.Get(ctx, "job-9")
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+}
+
+func TestJobRepositoryList(t *testing.T) {
+	ctx, repo, mockPool := setupJobRepo(t)
+	defer mockPool.Close()
+	createdAt := time.Date(2025, 1, 6, 2, 0, 0, 0, time.UTC)
+	mockPool.ExpectQuery(regexp.QuoteMeta(listJobsQuery)).WithArgs("batch.DelinquencyUpdate", "", listJobsMax).
+		WillReturnRows(pgxmock.NewRows(jobRowColumns).AddRow("job-2", "batch.DelinquencyUpdate", jobs.StatusSucceeded,
+			"batch.DelinquencyUpdate@2025-01-06T02:00:00Z", nil, nil, "", 0, 0, 1, "host-2", createdAt, &createdAt, &createdAt, &createdAt))
+
+	found, err := repo.List(ctx, jobs.Filter{Kind: "batch.DelinquencyUpdate"})
+
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, "batch.DelinquencyUpdate@2025-01-06T02:00:00Z", found[0].DedupeKey)
+	assert.Equal(t, "host-2", found[0].Worker)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestJobRepositoryDeleteFinishedBefore(t *testing.T) {
+	ctx, repo, mockPool := setupJobRepo(t)
+	defer mockPool.Close()
+	cutoff := time.Date(2025, 1, 6, 8, 0, 0, 0, time.UTC)
+	mockPool.ExpectExec(regexp.QuoteMeta(deleteFinishedJobsQuery)).WithArgs(cutoff).
+		WillReturnResult(pgxmock.NewResult("DELETE", 4))
+
+	n, err := repo.DeleteFinishedBefore(ctx, cutoff)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), n)
+}
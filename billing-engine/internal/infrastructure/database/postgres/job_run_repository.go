@@ -0,0 +1,151 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"billing-engine/internal/jobs"
+	"billing-engine/internal/pkg/apperrors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const jobRunColumns = `id, name, COALESCE(job_id::text, ''), status, total, processed, failed, error_samples,
+        COALESCE(error, ''), started_at, updated_at, finished_at`
+
+// startJobRunQuery closes the runs of the same job left running by a worker
+// that stopped, in the statement that inserts the new run.
+const startJobRunQuery = `
+        WITH abandoned AS (
+            UPDATE job_runs SET status = 'FAILED', error = $4, updated_at = $3, finished_at = $3
+            WHERE job_id = NULLIF($2, '')::uuid AND status = 'RUNNING')
+        INSERT INTO job_runs (name, job_id, status, started_at, updated_at)
+        VALUES ($1, NULLIF($2, '')::uuid, 'RUNNING', $3, $3)
+        RETURNING id`
+
+const updateJobRunQuery = `
+        UPDATE job_runs SET total = $2, processed = $3, failed = $4, error_samples = $5, updated_at = $6
+        WHERE id = $1 AND status = 'RUNNING'`
+
+const finishJobRunQuery = `
+        UPDATE job_runs
+        SET status = $2, total = $3, processed = $4, failed = $5, error_samples = $6, error = NULLIF($7, ''),
+            updated_at = $8, finished_at = $8
+        WHERE id = $1`
+
+const getJobRunQuery = `SELECT ` + jobRunColumns + ` FROM job_runs WHERE id = $1 AND name = $2`
+
+const listJobRunsQuery = `
+        SELECT ` + jobRunColumns + `
+        FROM job_runs
+        WHERE name = $1
+        ORDER BY started_at DESC, id DESC
+        LIMIT $2`
+
+// listJobRunsMax bounds ListRuns when no limit is given.
+const listJobRunsMax = 100
+
+type JobRunRepository struct {
+	db     DBPool
+	logger *slog.Logger
+}
+
+var _ jobs.RunStore = (*JobRunRepository)(nil)
+
+func NewJobRunRepository(db DBPool, logger *slog.Logger) *JobRunRepository {
+	if db == nil {
+		panic("DBPool cannot be nil for JobRunRepository")
+	}
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+		logger.Warn("Warning: No logger provided to NewJobRunRepository, using default stderr handler")
+	}
+	return &JobRunRepository{db: db, logger: logger.With("component", "JobRunRepository")}
+}
+
+func (r *JobRunRepository) StartRun(ctx context.Context, run *jobs.Run) error {
+	err := r.db.QueryRow(ctx, startJobRunQuery, run.Name, run.JobID, run.StartedAt, jobs.AbandonedRunError).Scan(&run.ID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to record job run", slog.String("name", run.Name), slog.Any("error", err))
+		return fmt.Errorf("%w: failed to record job run: %w", apperrors.ErrDatabase, err)
+	}
+	return nil
+}
+
+func (r *JobRunRepository) UpdateRun(ctx context.Context, run *jobs.Run) error {
+	samples, err := json.Marshal(run.ErrorSamples)
+	if err != nil {
+		return fmt.Errorf("%w: %w", apperrors.ErrInternalServer, err)
+	}
+	if _, err := r.db.Exec(ctx, updateJobRunQuery, run.ID, run.Total, run.Processed, run.Failed, samples, run.UpdatedAt); err != nil {
+		return fmt.Errorf("%w: failed to record job run progress: %w", apperrors.ErrDatabase, err)
+	}
+	return nil
+}
+
+func (r *JobRunRepository) FinishRun(ctx context.Context, run *jobs.Run) error {
+	samples, err := json.Marshal(run.ErrorSamples)
+	if err != nil {
+		return fmt.Errorf("%w: %w", apperrors.ErrInternalServer, err)
+	}
+	_, err = r.db.Exec(ctx, finishJobRunQuery, run.ID, run.Status, run.Total, run.Processed, run.Failed, samples, run.Error, run.FinishedAt)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to record finished job run", slog.Int64("runId", run.ID), slog.Any("error", err))
+		return fmt.Errorf("%w: failed to record finished job run: %w", apperrors.ErrDatabase, err)
+	}
+	return nil
+}
+
+func (r *JobRunRepository) GetRun(ctx context.Context, name string, id int64) (*jobs.Run, error) {
+	run, err := scanJobRun(r.db.QueryRow(ctx, getJobRunQuery, id, name))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: job %s has no run %d", apperrors.ErrNotFound, name, id)
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to get job run", slog.Int64("runId", id), slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to get job run: %w", apperrors.ErrDatabase, err)
+	}
+	return run, nil
+}
+
+func (r *JobRunRepository) ListRuns(ctx context.Context, name string, limit int) ([]jobs.Run, error) {
+	if limit <= 0 {
+		limit = listJobRunsMax
+	}
+	rows, err := r.db.Query(ctx, listJobRunsQuery, name, limit)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query job runs", slog.String("name", name), slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to list job runs: %w", apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	found := []jobs.Run{}
+	for rows.Next() {
+		run, err := scanJobRun(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to scan job run: %w", apperrors.ErrDatabase, err)
+		}
+		found = append(found, *run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: failed to iterate job runs: %w", apperrors.ErrDatabase, err)
+	}
+	return found, nil
+}
+
+func scanJobRun(row pgx.Row) (*jobs.Run, error) {
+	var run jobs.Run
+	var samples []byte
+	if err := row.Scan(&run.ID, &run.Name, &run.JobID, &run.Status, &run.Total, &run.Processed, &run.Failed, &samples,
+		&run.Error, &run.StartedAt, &run.UpdatedAt, &run.FinishedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(samples, &run.ErrorSamples); err != nil {
+		return nil, fmt.Errorf("unreadable error samples: %w", err)
+	}
+	return &run, nil
+}
@@ -0,0 +1,105 @@
+package postgres
+
+import (
+	"billing-engine/internal/jobs"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var jobRunRowColumns = []string{"id", "name", "job_id", "status", "total", "processed", "failed", "error_samples",
+	"error", "started_at", "updated_at", "finished_at"}
+
+func setupJobRunRepo(t *testing.T) (context.Context, *JobRunRepository, pgxmock.PgxPoolIface) {
+	t.Helper()
+	mockPool, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("failed to open a stub database connection: %v", err)
+	}
+	return context.Background(), NewJobRunRepository(mockPool, logger), mockPool
+}
+
+func TestJobRunRepositoryStartRun(t *testing.T) {
+	ctx, repo, mockPool := setupJobRunRepo(t)
+	defer mockPool.Close()
+	startedAt := time.Date(2025, 1, 6, 2, 0, 0, 0, time.UTC)
+	mockPool.ExpectQuery(regexp.QuoteMeta(startJobRunQuery)).
+		WithArgs("DelinquencyUpdate", "job-1", startedAt, jobs.AbandonedRunError).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int64(12)))
+
+	run := &jobs.Run{Name: "DelinquencyUpdate", JobID: "job-1", Status: jobs.StatusRunning, StartedAt: startedAt}
+	require.NoError(t, repo.StartRun(ctx, run))
+
+	assert.Equal(t, int64(12), run.ID)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestJobRunRepositoryFinishRun(t *testing.T) {
+	ctx, repo, mockPool := setupJobRunRepo(t)
+	defer mockPool.Close()
+	finishedAt := time.Date(2025, 1, 6, 2, 5, 0, 0, time.UTC)
+	mockPool.ExpectExec(regexp.QuoteMeta(finishJobRunQuery)).
+		WithArgs(int64(12), jobs.StatusFailed, 40, 40, 1, []byte(`["loan 7: timeout"]`), "1 of 40 loans failed", &finishedAt).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	err := repo.FinishRun(ctx, &jobs.Run{ID: 12, Status: jobs.StatusFailed, Total: 40, Processed: 40, Failed: 1,
+		ErrorSamples: []string{"loan 7: timeout"}, Error: "1 of 40 loans failed", FinishedAt: &finishedAt})
+
+	require.NoError(t, err)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestJobRunRepositoryGetRun(t *testing.T) {
+	startedAt := time.Date(2025, 1, 6, 2, 0, 0, 0, time.UTC)
+
+	t.Run("returns the run", func(t *testing.T) {
+		ctx, repo, mockPool := setupJobRunRepo(t)
+		defer mockPool.Close()
+		mockPool.ExpectQuery(regexp.QuoteMeta(getJobRunQuery)).WithArgs(int64(12), "DelinquencyUpdate").
+			WillReturnRows(pgxmock.NewRows(jobRunRowColumns).AddRow(int64(12), "DelinquencyUpdate", "job-1", jobs.StatusRunning,
+				40, 10, 1, []byte(`["loan 7: timeout"]`), "", startedAt, startedAt.Add(5*time.Second), nil))
+
+		run, err := repo.GetRun(ctx, "DelinquencyUpdate", 12)
+
+		require.NoError(t, err)
+		assert.Equal(t, 10, run.Processed)
+		assert.Equal(t, []string{"loan 7: timeout"}, run.ErrorSamples)
+		assert.Nil(t, run.FinishedAt)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("unknown run", func(t *testing.T) {
+		ctx, repo, mockPool := setupJobRunRepo(t)
+		defer mockPool.Close()
+		mockPool.ExpectQuery(regexp.QuoteMeta(getJobRunQuery)).WithArgs(int64(99), "DelinquencyUpdate").
+			WillReturnRows(pgxmock.NewRows(jobRunRowColumns))
+
+		_, err := repo.GetRun(ctx, "DelinquencyUpdate", 99)
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+}
+
+func TestJobRunRepositoryListRuns(t *testing.T) {
+	ctx, repo, mockPool := setupJobRunRepo(t)
+	defer mockPool.Close()
+	startedAt := time.Date(2025, 1, 6, 2, 0, 0, 0, time.UTC)
+	finishedAt := startedAt.Add(time.Minute)
+	mockPool.ExpectQuery(regexp.QuoteMeta(listJobRunsQuery)).WithArgs("LoanSnapshot", listJobRunsMax).
+		WillReturnRows(pgxmock.NewRows(jobRunRowColumns).AddRow(int64(3), "LoanSnapshot", "", jobs.StatusSucceeded,
+			0, 120, 0, []byte(`[]`), "", startedAt, finishedAt, &finishedAt))
+
+	runs, err := repo.ListRuns(ctx, "LoanSnapshot", 0)
+
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, 120, runs[0].Processed)
+	assert.Equal(t, time.Minute, runs[0].Duration(finishedAt))
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
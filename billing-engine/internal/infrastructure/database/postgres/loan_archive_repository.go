@@ -0,0 +1,294 @@
+package postgres
+
+import (
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/infrastructure/monitoring"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// archivedTable is a live table rows are archived from and the column that
+// names their loan.
+type archivedTable struct {
+	name       string
+	loanColumn string
+}
+
+// archivedTables lists the tables of a loan, parents before the rows that
+// reference them, which is the order a restore inserts in.
+var archivedTables = []archivedTable{
+	{"loans", "id"},
+	{"loan_schedule", "loan_id"},
+	{"direct_debit_instructions", "loan_id"},
+	{"loan_status_snapshots", "loan_id"},
+	{"collection_assignments", "loan_id"},
+	{"collection_actions", "loan_id"},
+	{"loan_holds", "loan_id"},
+	{"notes", "loan_id"},
+	{"attachments", "loan_id"},
+	{"rate_history", "loan_id"},
+	{"schedule_adjustments", "loan_id"},
+	{"prepayments", "loan_id"},
+	{"fees", "loan_id"},
+	{"fee_waivers", "loan_id"},
+	{"tax_lines", "loan_id"},
+	{"payments", "loan_id"},
+	{"documents", "loan_id"},
+	{"loan_agreements", "loan_id"},
+}
+
+func (t archivedTable) archiveQuery() string {
+	return `INSERT INTO ` + t.name + `_archive SELECT * FROM ` + t.name + ` WHERE ` + t.loanColumn + ` = $1`
+}
+
+func (t archivedTable) restoreQuery() string {
+	return `INSERT INTO ` + t.name + ` SELECT * FROM ` + t.name + `_archive WHERE ` + t.loanColumn + ` = $1`
+}
+
+func (t archivedTable) purgeQuery() string {
+	return `DELETE FROM ` + t.name + `_archive WHERE ` + t.loanColumn + ` = $1`
+}
+
+const archivedLoanColumns = `loan_id, public_id, customer_id, completed_at, archived_at`
+
+const (
+	// archivableConditions leaves out loans someone is still working on.
+	archivableConditions = `
+          AND NOT EXISTS (SELECT 1 FROM loan_holds h WHERE h.loan_id = l.id AND h.released_at IS NULL)
+          AND NOT EXISTS (SELECT 1 FROM collection_assignments a WHERE a.loan_id = l.id AND a.status = 'OPEN')`
+
+	// loanCompletedAt is the last payment of a grouped loan, or its last
+	// update when it was paid off without one.
+	loanCompletedAt = `COALESCE(MAX(s.payment_date), l.updated_at)`
+
+	listArchivableLoansQuery = `
+        SELECT l.id
+        FROM loans l
+        LEFT JOIN loan_schedule s ON s.loan_id = l.id
+        WHERE l.status = 'PAID_OFF'` + archivableConditions + `
+        GROUP BY l.id
+        HAVING ` + loanCompletedAt + ` < $1
+        ORDER BY ` + loanCompletedAt + `, l.id
+        LIMIT $2`
+
+	lockLoanForArchiveQuery = `SELECT id FROM loans WHERE id = $1 FOR UPDATE`
+
+	archivableLoanQuery = `
+        SELECT l.public_id, (SELECT c.id FROM customers c WHERE c.loan_id = l.id), ` + loanCompletedAt + `
+        FROM loans l
+        LEFT JOIN loan_schedule s ON s.loan_id = l.id
+        WHERE l.id = $1 AND l.status = 'PAID_OFF'` + archivableConditions + `
+        GROUP BY l.id
+        HAVING ` + loanCompletedAt + ` < $2`
+
+	insertArchivedLoanQuery = `INSERT INTO archived_loans (` + archivedLoanColumns + `) VALUES ($1, $2, $3, $4, $5)`
+	deleteArchivedLoanQuery = `DELETE FROM loans WHERE id = $1`
+
+	lockArchivedLoanQuery  = `SELECT ` + archivedLoanColumns + ` FROM archived_loans WHERE loan_id = $1 FOR UPDATE`
+	listArchivedLoansQuery = `SELECT ` + archivedLoanColumns + ` FROM archived_loans ORDER BY archived_at DESC, loan_id DESC LIMIT $1`
+	purgeArchivedLoanQuery = `DELETE FROM archived_loans WHERE loan_id = $1`
+	relinkCustomerQuery    = `UPDATE customers SET loan_id = $1, updated_at = $3 WHERE id = $2 AND loan_id IS NULL`
+)
+
+// restoreFixupQueries apply to archived rows what the foreign keys did to
+// live ones while the loan was away: a deleted rule or customer is cleared
+// from its assignments and a deleted mandate takes its instructions along.
+var restoreFixupQueries = []string{
+	`UPDATE collection_assignments_archive SET rule_id = NULL
+        WHERE loan_id = $1 AND rule_id IS NOT NULL
+          AND NOT EXISTS (SELECT 1 FROM collection_rules r WHERE r.id = collection_assignments_archive.rule_id)`,
+	`UPDATE collection_assignments_archive SET customer_id = NULL
+        WHERE loan_id = $1 AND customer_id IS NOT NULL
+          AND NOT EXISTS (SELECT 1 FROM customers c WHERE c.id = collection_assignments_archive.customer_id)`,
+	`DELETE FROM direct_debit_instructions_archive
+        WHERE loan_id = $1
+          AND NOT EXISTS (SELECT 1 FROM mandates m WHERE m.id = direct_debit_instructions_archive.mandate_id)`,
+}
+
+func archivedLoanFields(a *loan.ArchivedLoan) []any {
+	return []any{&a.LoanID, &a.PublicID, &a.CustomerID, &a.CompletedAt, &a.ArchivedAt}
+}
+
+func (r *LoanRepository) ListArchivable(ctx context.Context, cutoff time.Time, limit int) ([]int64, error) {
+	start := time.Now()
+	rows, err := r.db.Query(ctx, listArchivableLoansQuery, cutoff, limit)
+	if err != nil {
+		monitoring.RecordDBQuery("ListArchivableLoans", "error", time.Since(start))
+		r.logger.ErrorContext(ctx, "Failed to query archivable loans", "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	monitoring.RecordDBQuery("ListArchivableLoans", "success", time.Since(start))
+	return ids, nil
+}
+
+func (r *LoanRepository) ArchiveLoan(ctx context.Context, loanID int64, cutoff, archivedAt time.Time) (*loan.ArchivedLoan, error) {
+	start := time.Now()
+	tx, err := r.beginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer r.rollbackTx(ctx, tx)
+
+	archived, err := r.archiveLoan(ctx, tx, loanID, cutoff, archivedAt)
+	if err == nil {
+		err = r.commitTx(ctx, tx)
+	}
+	if err != nil {
+		monitoring.RecordDBQuery("ArchiveLoan", "error", time.Since(start))
+		return nil, err
+	}
+	monitoring.RecordDBQuery("ArchiveLoan", "success", time.Since(start))
+	r.logger.InfoContext(ctx, "Loan archived in DB", "loan_id", loanID)
+	return archived, nil
+}
+
+func (r *LoanRepository) archiveLoan(ctx context.Context, tx pgx.Tx, loanID int64, cutoff, archivedAt time.Time) (*loan.ArchivedLoan, error) {
+	var id int64
+	err := tx.QueryRow(ctx, lockLoanForArchiveQuery, loanID).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: loan %d", apperrors.ErrNotFound, loanID)
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to lock loan for archival", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+
+	archived := loan.ArchivedLoan{LoanID: loanID, ArchivedAt: archivedAt}
+	err = tx.QueryRow(ctx, archivableLoanQuery, loanID, cutoff).Scan(&archived.PublicID, &archived.CustomerID, &archived.CompletedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: loan %d no longer qualifies for archival", apperrors.ErrConflict, loanID)
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to check loan for archival", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+
+	if _, err := tx.Exec(ctx, insertArchivedLoanQuery, loanID, archived.PublicID, archived.CustomerID, archived.CompletedAt, archivedAt); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to catalogue archived loan", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	for _, t := range archivedTables {
+		if _, err := tx.Exec(ctx, t.archiveQuery(), loanID); err != nil {
+			r.logger.ErrorContext(ctx, "Failed to copy rows to the archive", "loan_id", loanID, "table", t.name, "error", err)
+			return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+		}
+	}
+	// The foreign keys cascade the delete to every other table above and
+	// unassign the loan from its customer.
+	if _, err := tx.Exec(ctx, deleteArchivedLoanQuery, loanID); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to delete archived loan", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	return &archived, nil
+}
+
+func (r *LoanRepository) RestoreLoan(ctx context.Context, loanID int64, restoredAt time.Time) (*loan.ArchivedLoan, bool, error) {
+	start := time.Now()
+	tx, err := r.beginTx(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	defer r.rollbackTx(ctx, tx)
+
+	restored, relinked, err := r.restoreLoan(ctx, tx, loanID, restoredAt)
+	if err == nil {
+		err = r.commitTx(ctx, tx)
+	}
+	if err != nil {
+		monitoring.RecordDBQuery("RestoreLoan", "error", time.Since(start))
+		return nil, false, err
+	}
+	monitoring.RecordDBQuery("RestoreLoan", "success", time.Since(start))
+	r.logger.InfoContext(ctx, "Loan restored from archive in DB", "loan_id", loanID, "relinked", relinked)
+	return restored, relinked, nil
+}
+
+func (r *LoanRepository) restoreLoan(ctx context.Context, tx pgx.Tx, loanID int64, restoredAt time.Time) (*loan.ArchivedLoan, bool, error) {
+	var archived loan.ArchivedLoan
+	err := tx.QueryRow(ctx, lockArchivedLoanQuery, loanID).Scan(archivedLoanFields(&archived)...)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, fmt.Errorf("%w: loan %d is not archived", apperrors.ErrNotFound, loanID)
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to lock archived loan", "loan_id", loanID, "error", err)
+		return nil, false, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+
+	for _, query := range restoreFixupQueries {
+		if _, err := tx.Exec(ctx, query, loanID); err != nil {
+			r.logger.ErrorContext(ctx, "Failed to reconcile archived rows", "loan_id", loanID, "error", err)
+			return nil, false, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+		}
+	}
+	for _, t := range archivedTables {
+		if _, err := tx.Exec(ctx, t.restoreQuery(), loanID); err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && (pgErr.Code == "23505" || pgErr.Code == "23503") {
+				return nil, false, fmt.Errorf("%w: restoring loan %d clashes with live %s: %s", apperrors.ErrConflict, loanID, t.name, pgErr.Message)
+			}
+			r.logger.ErrorContext(ctx, "Failed to restore archived rows", "loan_id", loanID, "table", t.name, "error", err)
+			return nil, false, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+		}
+	}
+	for _, t := range archivedTables {
+		if _, err := tx.Exec(ctx, t.purgeQuery(), loanID); err != nil {
+			r.logger.ErrorContext(ctx, "Failed to delete archived rows", "loan_id", loanID, "table", t.name, "error", err)
+			return nil, false, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+		}
+	}
+	if _, err := tx.Exec(ctx, purgeArchivedLoanQuery, loanID); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to delete archive catalogue entry", "loan_id", loanID, "error", err)
+		return nil, false, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+
+	if archived.CustomerID == nil {
+		return &archived, false, nil
+	}
+	cmdTag, err := tx.Exec(ctx, relinkCustomerQuery, loanID, *archived.CustomerID, restoredAt)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to reassign restored loan", "loan_id", loanID, "customer_id", *archived.CustomerID, "error", err)
+		return nil, false, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	return &archived, cmdTag.RowsAffected() == 1, nil
+}
+
+func (r *LoanRepository) ListArchived(ctx context.Context, limit int) ([]loan.ArchivedLoan, error) {
+	rows, err := r.db.Query(ctx, listArchivedLoansQuery, limit)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query archived loans", "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	archived := []loan.ArchivedLoan{}
+	for rows.Next() {
+		var a loan.ArchivedLoan
+		if err := rows.Scan(archivedLoanFields(&a)...); err != nil {
+			return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+		}
+		archived = append(archived, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	return archived, nil
+}
@@ -0,0 +1,189 @@
+package postgres
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoanRepositoryListArchivable(t *testing.T) {
+	ctx, repo, mockPool := setupLoanRepo(t)
+	defer mockPool.Close()
+	cutoff := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	mockPool.ExpectQuery(regexp.QuoteMeta(listArchivableLoansQuery)).WithArgs(cutoff, 50).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int64(3)).AddRow(int64(8)))
+
+	ids, err := repo.ListArchivable(ctx, cutoff, 50)
+
+	require.NoError(t, err)
+	assert.Equal(t, []int64{3, 8}, ids)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestLoanRepositoryArchiveLoan(t *testing.T) {
+	cutoff := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	completedAt := cutoff.AddDate(0, -3, 0)
+	archivedAt := time.Date(2025, 1, 1, 4, 0, 0, 0, time.UTC)
+	publicID := uuid.New()
+	customerID := int64(21)
+
+	t.Run("copies every table and deletes the loan", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+		mockPool.ExpectBegin()
+		mockPool.ExpectQuery(regexp.QuoteMeta(lockLoanForArchiveQuery)).WithArgs(int64(7)).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int64(7)))
+		mockPool.ExpectQuery(regexp.QuoteMeta(archivableLoanQuery)).WithArgs(int64(7), cutoff).
+			WillReturnRows(pgxmock.NewRows([]string{"public_id", "customer_id", "completed_at"}).AddRow(publicID, &customerID, completedAt))
+		mockPool.ExpectExec(regexp.QuoteMeta(insertArchivedLoanQuery)).
+			WithArgs(int64(7), publicID, &customerID, completedAt, archivedAt).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		for _, table := range archivedTables {
+			mockPool.ExpectExec(regexp.QuoteMeta(table.archiveQuery())).WithArgs(int64(7)).
+				WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		}
+		mockPool.ExpectExec(regexp.QuoteMeta(deleteArchivedLoanQuery)).WithArgs(int64(7)).
+			WillReturnResult(pgxmock.NewResult("DELETE", 1))
+		mockPool.ExpectCommit()
+		mockPool.ExpectRollback()
+
+		archived, err := repo.ArchiveLoan(ctx, 7, cutoff, archivedAt)
+
+		require.NoError(t, err)
+		assert.Equal(t, publicID, archived.PublicID)
+		assert.Equal(t, &customerID, archived.CustomerID)
+		assert.Equal(t, completedAt, archived.CompletedAt)
+		assert.Equal(t, archivedAt, archived.ArchivedAt)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("unknown loan", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+		mockPool.ExpectBegin()
+		mockPool.ExpectQuery(regexp.QuoteMeta(lockLoanForArchiveQuery)).WithArgs(int64(7)).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}))
+		mockPool.ExpectRollback()
+
+		_, err := repo.ArchiveLoan(ctx, 7, cutoff, archivedAt)
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("loan that no longer qualifies", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+		mockPool.ExpectBegin()
+		mockPool.ExpectQuery(regexp.QuoteMeta(lockLoanForArchiveQuery)).WithArgs(int64(7)).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int64(7)))
+		mockPool.ExpectQuery(regexp.QuoteMeta(archivableLoanQuery)).WithArgs(int64(7), cutoff).
+			WillReturnRows(pgxmock.NewRows([]string{"public_id", "customer_id", "completed_at"}))
+		mockPool.ExpectRollback()
+
+		_, err := repo.ArchiveLoan(ctx, 7, cutoff, archivedAt)
+
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+}
+
+func TestLoanRepositoryRestoreLoan(t *testing.T) {
+	restoredAt := time.Date(2025, 2, 1, 9, 0, 0, 0, time.UTC)
+	customerID := int64(21)
+	cols := []string{"loan_id", "public_id", "customer_id", "completed_at", "archived_at"}
+	catalogued := func() *pgxmock.Rows {
+		return pgxmock.NewRows(cols).AddRow(int64(7), uuid.New(), &customerID, restoredAt.AddDate(-3, 0, 0), restoredAt.AddDate(0, -1, 0))
+	}
+	expectFixups := func(mockPool pgxmock.PgxPoolIface) {
+		for _, query := range restoreFixupQueries {
+			mockPool.ExpectExec(regexp.QuoteMeta(query)).WithArgs(int64(7)).WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+		}
+	}
+
+	t.Run("moves the rows back and reassigns the customer", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+		mockPool.ExpectBegin()
+		mockPool.ExpectQuery(regexp.QuoteMeta(lockArchivedLoanQuery)).WithArgs(int64(7)).WillReturnRows(catalogued())
+		expectFixups(mockPool)
+		for _, table := range archivedTables {
+			mockPool.ExpectExec(regexp.QuoteMeta(table.restoreQuery())).WithArgs(int64(7)).
+				WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		}
+		for _, table := range archivedTables {
+			mockPool.ExpectExec(regexp.QuoteMeta(table.purgeQuery())).WithArgs(int64(7)).
+				WillReturnResult(pgxmock.NewResult("DELETE", 1))
+		}
+		mockPool.ExpectExec(regexp.QuoteMeta(purgeArchivedLoanQuery)).WithArgs(int64(7)).
+			WillReturnResult(pgxmock.NewResult("DELETE", 1))
+		mockPool.ExpectExec(regexp.QuoteMeta(relinkCustomerQuery)).WithArgs(int64(7), customerID, restoredAt).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mockPool.ExpectCommit()
+		mockPool.ExpectRollback()
+
+		restored, relinked, err := repo.RestoreLoan(ctx, 7, restoredAt)
+
+		require.NoError(t, err)
+		assert.True(t, relinked)
+		assert.Equal(t, int64(7), restored.LoanID)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("not archived", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+		mockPool.ExpectBegin()
+		mockPool.ExpectQuery(regexp.QuoteMeta(lockArchivedLoanQuery)).WithArgs(int64(7)).WillReturnRows(pgxmock.NewRows(cols))
+		mockPool.ExpectRollback()
+
+		_, _, err := repo.RestoreLoan(ctx, 7, restoredAt)
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("clash with a live row", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+		mockPool.ExpectBegin()
+		mockPool.ExpectQuery(regexp.QuoteMeta(lockArchivedLoanQuery)).WithArgs(int64(7)).WillReturnRows(catalogued())
+		expectFixups(mockPool)
+		mockPool.ExpectExec(regexp.QuoteMeta(archivedTables[0].restoreQuery())).WithArgs(int64(7)).
+			WillReturnError(&pgconn.PgError{Code: "23505", Message: "duplicate key value violates unique constraint"})
+		mockPool.ExpectRollback()
+
+		_, _, err := repo.RestoreLoan(ctx, 7, restoredAt)
+
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+}
+
+func TestLoanRepositoryListArchived(t *testing.T) {
+	ctx, repo, mockPool := setupLoanRepo(t)
+	defer mockPool.Close()
+	archivedAt := time.Date(2025, 1, 1, 4, 0, 0, 0, time.UTC)
+	mockPool.ExpectQuery(regexp.QuoteMeta(listArchivedLoansQuery)).WithArgs(10).
+		WillReturnRows(pgxmock.NewRows([]string{"loan_id", "public_id", "customer_id", "completed_at", "archived_at"}).
+			AddRow(int64(7), uuid.New(), nil, archivedAt.AddDate(-3, 0, 0), archivedAt))
+
+	archived, err := repo.ListArchived(ctx, 10)
+
+	require.NoError(t, err)
+	require.Len(t, archived, 1)
+	assert.Nil(t, archived[0].CustomerID)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+
+	mockPool.ExpectQuery(regexp.QuoteMeta(listArchivedLoansQuery)).WillReturnError(errors.New("connection reset"))
+	_, err = repo.ListArchived(ctx, 10)
+	assert.ErrorIs(t, err, apperrors.ErrDatabase)
+}
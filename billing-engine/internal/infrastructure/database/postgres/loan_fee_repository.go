@@ -0,0 +1,288 @@
+package postgres
+
+import (
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/infrastructure/monitoring"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	// feeColumns selects a fee and its tax line from feesFrom; the tax line's
+	// columns are NULL when the fee was not taxed.
+	feeColumns = `f.id, f.loan_id, f.fee_type, f.amount, f.reason, f.posted_by, f.posted_at, f.paid_at,
+        t.id, t.jurisdiction, t.rate, t.taxable_amount, t.amount, t.created_at`
+	feesFrom         = ` FROM fees f LEFT JOIN tax_lines t ON t.fee_id = f.id`
+	feeWaiverColumns = `id, fee_id, loan_id, reason, status, requested_by, requested_at, decided_by, decided_at`
+)
+
+const (
+	postFeeQuery = `INSERT INTO fees (loan_id, fee_type, amount, reason, posted_by, posted_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+	// postTaxedFeeQuery stores a fee and its tax line in one statement.
+	postTaxedFeeQuery = `
+        WITH fee AS (
+            INSERT INTO fees (loan_id, fee_type, amount, reason, posted_by, posted_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id
+        )
+        INSERT INTO tax_lines (fee_id, loan_id, jurisdiction, rate, taxable_amount, amount, created_at)
+        SELECT id, $1, $7, $8, $9, $10, $6 FROM fee
+        RETURNING fee_id, id`
+	getFeesQuery       = `SELECT ` + feeColumns + feesFrom + ` WHERE f.loan_id = $1 ORDER BY f.id`
+	getFeeWaiversQuery = `SELECT ` + feeWaiverColumns + ` FROM fee_waivers WHERE loan_id = $1 ORDER BY id`
+	// The fee is looked up with its loan so a waiver cannot be filed against
+	// another loan's fee.
+	requestFeeWaiverQuery = `
+        INSERT INTO fee_waivers (fee_id, loan_id, reason, status, requested_by, requested_at)
+        SELECT f.id, f.loan_id, $3, $4, $5, $6 FROM fees f WHERE f.id = $1 AND f.loan_id = $2
+        RETURNING id`
+	decideFeeWaiverQuery = `UPDATE fee_waivers SET status = $2, decided_by = $3, decided_at = $4 WHERE id = $1 AND status = 'PENDING'`
+	// openFeesWhere keeps the loan's fees that are neither paid nor waived.
+	openFeesWhere = `
+        WHERE f.loan_id = $1 AND f.paid_at IS NULL
+          AND NOT EXISTS (SELECT 1 FROM fee_waivers w WHERE w.fee_id = f.id AND w.status = 'APPROVED')`
+	listOpenFeesQuery = `SELECT ` + feeColumns + feesFrom + openFeesWhere + ` ORDER BY f.id`
+	// findOldestOpenFeeQuery locks the fee and leaves its tax line, which is
+	// never updated, unlocked.
+	findOldestOpenFeeQuery = `SELECT ` + feeColumns + feesFrom + openFeesWhere + ` ORDER BY f.id LIMIT 1 FOR UPDATE OF f`
+	settleFeeQuery         = `UPDATE fees SET paid_at = $2 WHERE id = $1 AND paid_at IS NULL`
+	// sumTaxLinesQuery counts a line as waived when its fee has an approved
+	// waiver now, whenever that was decided. Lines of archived loans are read
+	// from the archive tables, so archival leaves past periods unchanged.
+	sumTaxLinesQuery = `
+        SELECT jurisdiction, fee_type, COUNT(*), SUM(taxable_amount), SUM(amount),
+               SUM(CASE WHEN waived THEN amount ELSE 0 END)
+        FROM (
+            SELECT t.jurisdiction, f.fee_type, t.taxable_amount, t.amount,
+                   EXISTS (SELECT 1 FROM fee_waivers w WHERE w.fee_id = t.fee_id AND w.status = 'APPROVED') AS waived
+            FROM tax_lines t
+            JOIN fees f ON f.id = t.fee_id
+            WHERE t.created_at >= $1 AND t.created_at < $2
+            UNION ALL
+            SELECT t.jurisdiction, f.fee_type, t.taxable_amount, t.amount,
+                   EXISTS (SELECT 1 FROM fee_waivers_archive w WHERE w.fee_id = t.fee_id AND w.status = 'APPROVED')
+            FROM tax_lines_archive t
+            JOIN fees_archive f ON f.id = t.fee_id
+            WHERE t.created_at >= $1 AND t.created_at < $2
+        ) lines
+        GROUP BY jurisdiction, fee_type
+        ORDER BY jurisdiction, fee_type`
+)
+
+// feeRow scans a row of feeColumns.
+type feeRow struct {
+	fee          loan.Fee
+	taxID        *int64
+	jurisdiction *string
+	rate         *float64
+	taxable      *float64
+	taxAmount    *float64
+	taxedAt      *time.Time
+}
+
+func (r *feeRow) fields() []any {
+	f := &r.fee
+	return []any{&f.ID, &f.LoanID, &f.Type, &f.Amount, &f.Reason, &f.PostedBy, &f.PostedAt, &f.PaidAt,
+		&r.taxID, &r.jurisdiction, &r.rate, &r.taxable, &r.taxAmount, &r.taxedAt}
+}
+
+// result is the scanned fee with its tax line, if it has one, and empty
+// waivers.
+func (r *feeRow) result() loan.Fee {
+	f := r.fee
+	f.Waivers = []loan.FeeWaiver{}
+	if r.taxID != nil {
+		f.Tax = &loan.TaxLine{ID: *r.taxID, FeeID: f.ID, LoanID: f.LoanID, Jurisdiction: *r.jurisdiction, Rate: *r.rate,
+			TaxableAmount: *r.taxable, Amount: *r.taxAmount, CreatedAt: *r.taxedAt}
+	}
+	return f
+}
+
+func feeWaiverFields(w *loan.FeeWaiver) []any {
+	return []any{&w.ID, &w.FeeID, &w.LoanID, &w.Reason, &w.Status, &w.RequestedBy, &w.RequestedAt, &w.DecidedBy, &w.DecidedAt}
+}
+
+func (r *LoanRepository) PostFee(ctx context.Context, fee *loan.Fee) error {
+	start := time.Now()
+	var err error
+	if tax := fee.Tax; tax != nil {
+		err = r.db.QueryRow(ctx, postTaxedFeeQuery, fee.LoanID, fee.Type, fee.Amount, fee.Reason, fee.PostedBy, fee.PostedAt,
+			tax.Jurisdiction, tax.Rate, tax.TaxableAmount, tax.Amount).Scan(&fee.ID, &tax.ID)
+		tax.FeeID = fee.ID
+	} else {
+		err = r.db.QueryRow(ctx, postFeeQuery, fee.LoanID, fee.Type, fee.Amount, fee.Reason, fee.PostedBy, fee.PostedAt).Scan(&fee.ID)
+	}
+	if err == nil {
+		monitoring.RecordDBQuery("PostFee", "success", time.Since(start))
+		return nil
+	}
+	monitoring.RecordDBQuery("PostFee", "error", time.Since(start))
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		return fmt.Errorf("%w: loan %d", apperrors.ErrNotFound, fee.LoanID)
+	}
+	r.logger.ErrorContext(ctx, "Failed to insert fee", "loan_id", fee.LoanID, "error", err)
+	return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+}
+
+// GetFees reads the fees and then their waivers, which it hands out to the
+// fees in memory.
+func (r *LoanRepository) GetFees(ctx context.Context, loanID int64) ([]loan.Fee, error) {
+	start := time.Now()
+	fees, err := r.queryFees(ctx, getFeesQuery, loanID)
+	if err != nil {
+		monitoring.RecordDBQuery("GetFees", "error", time.Since(start))
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, getFeeWaiversQuery, loanID)
+	if err != nil {
+		monitoring.RecordDBQuery("GetFees", "error", time.Since(start))
+		r.logger.ErrorContext(ctx, "Failed to read fee waivers", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+	byID := make(map[int64]*loan.Fee, len(fees))
+	for i := range fees {
+		byID[fees[i].ID] = &fees[i]
+	}
+	for rows.Next() {
+		var w loan.FeeWaiver
+		if err := rows.Scan(feeWaiverFields(&w)...); err != nil {
+			monitoring.RecordDBQuery("GetFees", "error", time.Since(start))
+			r.logger.ErrorContext(ctx, "Failed to scan fee waiver", "loan_id", loanID, "error", err)
+			return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+		}
+		if fee, ok := byID[w.FeeID]; ok {
+			fee.Waivers = append(fee.Waivers, w)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		monitoring.RecordDBQuery("GetFees", "error", time.Since(start))
+		r.logger.ErrorContext(ctx, "Error iterating fee waivers", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	monitoring.RecordDBQuery("GetFees", "success", time.Since(start))
+	return fees, nil
+}
+
+// queryFees runs query, which selects feeColumns for a loan, and returns the
+// fees with their tax lines and empty waivers.
+func (r *LoanRepository) queryFees(ctx context.Context, query string, loanID int64) ([]loan.Fee, error) {
+	rows, err := r.db.Query(ctx, query, loanID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to read fees", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	fees := []loan.Fee{}
+	for rows.Next() {
+		var row feeRow
+		if err := rows.Scan(row.fields()...); err != nil {
+			r.logger.ErrorContext(ctx, "Failed to scan fee", "loan_id", loanID, "error", err)
+			return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+		}
+		fees = append(fees, row.result())
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.ErrorContext(ctx, "Error iterating fees", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	return fees, nil
+}
+
+func (r *LoanRepository) RequestFeeWaiver(ctx context.Context, waiver *loan.FeeWaiver) error {
+	start := time.Now()
+	err := r.db.QueryRow(ctx, requestFeeWaiverQuery, waiver.FeeID, waiver.LoanID, waiver.Reason, waiver.Status,
+		waiver.RequestedBy, waiver.RequestedAt).Scan(&waiver.ID)
+	if err == nil {
+		monitoring.RecordDBQuery("RequestFeeWaiver", "success", time.Since(start))
+		return nil
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		monitoring.RecordDBQuery("RequestFeeWaiver", "not_found", time.Since(start))
+		return fmt.Errorf("%w: loan %d has no fee %d", apperrors.ErrNotFound, waiver.LoanID, waiver.FeeID)
+	}
+	monitoring.RecordDBQuery("RequestFeeWaiver", "error", time.Since(start))
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return fmt.Errorf("%w: fee %d already has a pending or approved waiver", apperrors.ErrConflict, waiver.FeeID)
+	}
+	r.logger.ErrorContext(ctx, "Failed to insert fee waiver", "fee_id", waiver.FeeID, "error", err)
+	return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+}
+
+func (r *LoanRepository) DecideFeeWaiver(ctx context.Context, waiver *loan.FeeWaiver) error {
+	start := time.Now()
+	cmdTag, err := r.db.Exec(ctx, decideFeeWaiverQuery, waiver.ID, waiver.Status, waiver.DecidedBy, waiver.DecidedAt)
+	if err != nil {
+		monitoring.RecordDBQuery("DecideFeeWaiver", "error", time.Since(start))
+		r.logger.ErrorContext(ctx, "Failed to decide fee waiver", "waiver_id", waiver.ID, "error", err)
+		return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	if cmdTag.RowsAffected() == 0 {
+		monitoring.RecordDBQuery("DecideFeeWaiver", "conflict", time.Since(start))
+		return fmt.Errorf("%w: waiver %d was already decided", apperrors.ErrConflict, waiver.ID)
+	}
+	monitoring.RecordDBQuery("DecideFeeWaiver", "success", time.Since(start))
+	return nil
+}
+
+func (t *loanTx) FindOldestOpenFeeForUpdate(ctx context.Context, loanID int64) (*loan.Fee, error) {
+	var row feeRow
+	err := t.tx.QueryRow(ctx, findOldestOpenFeeQuery, loanID).Scan(row.fields()...)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: loan %d has no open fee", apperrors.ErrNotFound, loanID)
+	}
+	if err != nil {
+		t.r.logger.ErrorContext(ctx, "Failed to find/lock oldest open fee", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	fee := row.result()
+	return &fee, nil
+}
+
+func (t *loanTx) SettleFee(ctx context.Context, fee *loan.Fee) error {
+	cmdTag, err := t.tx.Exec(ctx, settleFeeQuery, fee.ID, fee.PaidAt)
+	if err != nil {
+		t.r.logger.ErrorContext(ctx, "Failed to settle fee", "fee_id", fee.ID, "error", err)
+		return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	if cmdTag.RowsAffected() != 1 {
+		return fmt.Errorf("%w: fee %d is already paid", apperrors.ErrConflict, fee.ID)
+	}
+	return nil
+}
+
+func (r *LoanRepository) SumTaxLines(ctx context.Context, from, to time.Time) ([]loan.TaxTotals, error) {
+	start := time.Now()
+	rows, err := r.db.Query(ctx, sumTaxLinesQuery, from, to)
+	if err != nil {
+		monitoring.RecordDBQuery("SumTaxLines", "error", time.Since(start))
+		r.logger.ErrorContext(ctx, "Failed to sum tax lines", "from", from, "to", to, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	totals := make([]loan.TaxTotals, 0)
+	for rows.Next() {
+		var t loan.TaxTotals
+		if err := rows.Scan(&t.Jurisdiction, &t.FeeType, &t.Lines, &t.TaxableAmount, &t.Amount, &t.WaivedAmount); err != nil {
+			monitoring.RecordDBQuery("SumTaxLines", "error", time.Since(start))
+			return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+		}
+		totals = append(totals, t)
+	}
+	if err := rows.Err(); err != nil {
+		monitoring.RecordDBQuery("SumTaxLines", "error", time.Since(start))
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+
+	monitoring.RecordDBQuery("SumTaxLines", "success", time.Since(start))
+	return totals, nil
+}
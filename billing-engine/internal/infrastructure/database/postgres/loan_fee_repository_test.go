@@ -0,0 +1,221 @@
+package postgres
+
+import (
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/pkg/apperrors"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	feeColumnNames = []string{"id", "loan_id", "fee_type", "amount", "reason", "posted_by", "posted_at", "paid_at",
+		"tax_id", "jurisdiction", "rate", "taxable_amount", "tax_amount", "tax_created_at"}
+	feeWaiverColumnNames = []string{"id", "fee_id", "loan_id", "reason", "status", "requested_by", "requested_at", "decided_by", "decided_at"}
+	// untaxed are the tax line columns of a fee that was not taxed.
+	untaxed = []any{nil, nil, nil, nil, nil, nil}
+)
+
+// taxLineValues are the tax line columns of a taxed fee.
+func taxLineValues(id int64, jurisdiction string, rate, taxable, amount float64) []any {
+	createdAt := testClock.Now()
+	return []any{&id, &jurisdiction, &rate, &taxable, &amount, &createdAt}
+}
+
+func TestLoanRepositoryPostFee(t *testing.T) {
+	ctx, repo, mockPool := setupLoanRepo(t)
+	defer mockPool.Close()
+	teller := "teller"
+
+	fee := &loan.Fee{LoanID: 1, Type: loan.FeeProcessing, Amount: 15, Reason: "restructure request", PostedBy: &teller, PostedAt: testClock.Now()}
+	mockPool.ExpectQuery(regexp.QuoteMeta(postFeeQuery)).
+		WithArgs(int64(1), loan.FeeProcessing, 15.0, "restructure request", &teller, testClock.Now()).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int64(4)))
+	require.NoError(t, repo.PostFee(ctx, fee))
+	assert.Equal(t, int64(4), fee.ID)
+
+	taxed := &loan.Fee{LoanID: 1, Type: loan.FeeLegal, Amount: 50, Reason: "court filing", PostedBy: &teller, PostedAt: testClock.Now(),
+		Tax: &loan.TaxLine{LoanID: 1, Jurisdiction: "ID", Rate: 0.11, TaxableAmount: 50, Amount: 5.5, CreatedAt: testClock.Now()}}
+	mockPool.ExpectQuery(regexp.QuoteMeta(postTaxedFeeQuery)).
+		WithArgs(int64(1), loan.FeeLegal, 50.0, "court filing", &teller, testClock.Now(), "ID", 0.11, 50.0, 5.5).
+		WillReturnRows(pgxmock.NewRows([]string{"fee_id", "id"}).AddRow(int64(5), int64(7)))
+	require.NoError(t, repo.PostFee(ctx, taxed))
+	assert.Equal(t, int64(5), taxed.ID)
+	assert.Equal(t, int64(7), taxed.Tax.ID)
+	assert.Equal(t, int64(5), taxed.Tax.FeeID)
+
+	gone := &loan.Fee{LoanID: 2, Type: loan.FeeLegal, Amount: 50, Reason: "court filing", PostedAt: testClock.Now()}
+	mockPool.ExpectQuery(regexp.QuoteMeta(postFeeQuery)).WithArgs(int64(2), loan.FeeLegal, 50.0, "court filing", (*string)(nil), testClock.Now()).
+		WillReturnError(&pgconn.PgError{Code: "23503"})
+	assert.ErrorIs(t, repo.PostFee(ctx, gone), apperrors.ErrNotFound)
+
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestLoanRepositoryGetFees(t *testing.T) {
+	ctx, repo, mockPool := setupLoanRepo(t)
+	defer mockPool.Close()
+	teller, supervisor := "teller", "supervisor"
+	decidedAt, paidAt := testClock.Now(), testClock.Now()
+
+	mockPool.ExpectQuery(regexp.QuoteMeta(getFeesQuery)).WithArgs(int64(1)).
+		WillReturnRows(pgxmock.NewRows(feeColumnNames).
+			AddRow(append([]any{int64(4), int64(1), loan.FeeBounce, 25.0, "direct debit returned", &teller, testClock.Now(), nil}, untaxed...)...).
+			AddRow(append([]any{int64(5), int64(1), loan.FeeLegal, 50.0, "court filing", &teller, testClock.Now(), &paidAt}, taxLineValues(7, "ID", 0.11, 50, 5.5)...)...))
+	mockPool.ExpectQuery(regexp.QuoteMeta(getFeeWaiversQuery)).WithArgs(int64(1)).
+		WillReturnRows(pgxmock.NewRows(feeWaiverColumnNames).
+			AddRow(int64(8), int64(4), int64(1), "bank error", loan.WaiverRejected, &teller, testClock.Now(), &supervisor, &decidedAt).
+			AddRow(int64(9), int64(4), int64(1), "bank confirmed error", loan.WaiverApproved, &teller, testClock.Now(), &supervisor, &decidedAt))
+
+	fees, err := repo.GetFees(ctx, 1)
+
+	require.NoError(t, err)
+	require.Len(t, fees, 2)
+	require.Len(t, fees[0].Waivers, 2)
+	assert.True(t, fees[0].Waived())
+	assert.Nil(t, fees[0].Tax)
+	assert.Nil(t, fees[0].PaidAt)
+	assert.Equal(t, &paidAt, fees[1].PaidAt)
+	assert.Equal(t, &loan.TaxLine{ID: 7, FeeID: 5, LoanID: 1, Jurisdiction: "ID", Rate: 0.11, TaxableAmount: 50, Amount: 5.5, CreatedAt: testClock.Now()}, fees[1].Tax)
+	assert.NotNil(t, fees[1].Waivers)
+	assert.Empty(t, fees[1].Waivers)
+
+	mockPool.ExpectQuery(regexp.QuoteMeta(getFeesQuery)).WithArgs(int64(2)).WillReturnError(errors.New("connection reset"))
+	_, err = repo.GetFees(ctx, 2)
+	assert.ErrorIs(t, err, apperrors.ErrDatabase)
+
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestLoanRepositoryRequestFeeWaiver(t *testing.T) {
+	teller := "teller"
+	newWaiver := func() *loan.FeeWaiver {
+		return &loan.FeeWaiver{FeeID: 4, LoanID: 1, Reason: "bank error", Status: loan.WaiverPending, RequestedBy: &teller, RequestedAt: testClock.Now()}
+	}
+	expectInsert := func(mockPool pgxmock.PgxPoolIface) *pgxmock.ExpectedQuery {
+		return mockPool.ExpectQuery(regexp.QuoteMeta(requestFeeWaiverQuery)).
+			WithArgs(int64(4), int64(1), "bank error", loan.WaiverPending, &teller, testClock.Now())
+	}
+
+	t.Run("stores a pending waiver", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+		expectInsert(mockPool).WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int64(9)))
+		waiver := newWaiver()
+
+		require.NoError(t, repo.RequestFeeWaiver(ctx, waiver))
+
+		assert.Equal(t, int64(9), waiver.ID)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("reports a fee of another loan", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+		expectInsert(mockPool).WillReturnRows(pgxmock.NewRows([]string{"id"}))
+
+		assert.ErrorIs(t, repo.RequestFeeWaiver(ctx, newWaiver()), apperrors.ErrNotFound)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("reports an open waiver", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+		expectInsert(mockPool).WillReturnError(&pgconn.PgError{Code: "23505", ConstraintName: "uq_fee_waivers_open_fee"})
+
+		assert.ErrorIs(t, repo.RequestFeeWaiver(ctx, newWaiver()), apperrors.ErrConflict)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+}
+
+func TestLoanRepositoryDecideFeeWaiver(t *testing.T) {
+	ctx, repo, mockPool := setupLoanRepo(t)
+	defer mockPool.Close()
+	supervisor := "supervisor"
+	decidedAt := testClock.Now()
+	waiver := &loan.FeeWaiver{ID: 9, Status: loan.WaiverApproved, DecidedBy: &supervisor, DecidedAt: &decidedAt}
+
+	mockPool.ExpectExec(regexp.QuoteMeta(decideFeeWaiverQuery)).WithArgs(int64(9), loan.WaiverApproved, &supervisor, &decidedAt).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	require.NoError(t, repo.DecideFeeWaiver(ctx, waiver))
+
+	mockPool.ExpectExec(regexp.QuoteMeta(decideFeeWaiverQuery)).WithArgs(int64(9), loan.WaiverApproved, &supervisor, &decidedAt).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+	assert.ErrorIs(t, repo.DecideFeeWaiver(ctx, waiver), apperrors.ErrConflict)
+
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestLoanRepositoryFindOldestOpenFeeForUpdate(t *testing.T) {
+	t.Run("returns the fee with its tax line", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+		mockPool.ExpectQuery(regexp.QuoteMeta(findOldestOpenFeeQuery)).WithArgs(int64(1)).
+			WillReturnRows(pgxmock.NewRows(feeColumnNames).
+				AddRow(append([]any{int64(5), int64(1), loan.FeeLegal, 50.0, "court filing", nil, testClock.Now(), nil}, taxLineValues(7, "ID", 0.11, 50, 5.5)...)...))
+
+		fee, err := inTx(repo, mockPool).FindOldestOpenFeeForUpdate(ctx, 1)
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), fee.ID)
+		assert.Equal(t, 55.5, fee.Owed())
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("reports a loan without open fees", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+		mockPool.ExpectQuery(regexp.QuoteMeta(findOldestOpenFeeQuery)).WithArgs(int64(1)).
+			WillReturnRows(pgxmock.NewRows(feeColumnNames))
+
+		_, err := inTx(repo, mockPool).FindOldestOpenFeeForUpdate(ctx, 1)
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+}
+
+func TestLoanRepositorySettleFee(t *testing.T) {
+	ctx, repo, mockPool := setupLoanRepo(t)
+	defer mockPool.Close()
+	paidAt := testClock.Now()
+	fee := &loan.Fee{ID: 5, PaidAt: &paidAt}
+
+	mockPool.ExpectExec(regexp.QuoteMeta(settleFeeQuery)).WithArgs(int64(5), &paidAt).WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	assert.NoError(t, inTx(repo, mockPool).SettleFee(ctx, fee))
+
+	mockPool.ExpectExec(regexp.QuoteMeta(settleFeeQuery)).WithArgs(int64(5), &paidAt).WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+	assert.ErrorIs(t, inTx(repo, mockPool).SettleFee(ctx, fee), apperrors.ErrConflict)
+
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestLoanRepositorySumTaxLines(t *testing.T) {
+	ctx, repo, mockPool := setupLoanRepo(t)
+	defer mockPool.Close()
+	from := testClock.Now()
+	to := from.AddDate(0, 0, 1)
+
+	mockPool.ExpectQuery(regexp.QuoteMeta(sumTaxLinesQuery)).WithArgs(from, to).
+		WillReturnRows(pgxmock.NewRows([]string{"jurisdiction", "fee_type", "count", "taxable", "amount", "waived"}).
+			AddRow("ID", loan.FeeBounce, 2, 50.0, 5.5, 2.75).
+			AddRow("ID", loan.FeeLegal, 1, 100.0, 11.0, 0.0))
+
+	totals, err := repo.SumTaxLines(ctx, from, to)
+
+	require.NoError(t, err)
+	assert.Equal(t, []loan.TaxTotals{
+		{Jurisdiction: "ID", FeeType: loan.FeeBounce, Lines: 2, TaxableAmount: 50, Amount: 5.5, WaivedAmount: 2.75},
+		{Jurisdiction: "ID", FeeType: loan.FeeLegal, Lines: 1, TaxableAmount: 100, Amount: 11},
+	}, totals)
+
+	mockPool.ExpectQuery(regexp.QuoteMeta(sumTaxLinesQuery)).WithArgs(from, to).WillReturnError(errors.New("connection reset"))
+	_, err = repo.SumTaxLines(ctx, from, to)
+	assert.ErrorIs(t, err, apperrors.ErrDatabase)
+
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
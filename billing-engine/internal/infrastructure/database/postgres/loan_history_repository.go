@@ -0,0 +1,98 @@
+package postgres
+
+import (
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/infrastructure/monitoring"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var _ loan.HistoryRepository = (*LoanRepository)(nil)
+
+// The history tables store whole rows as JSONB; jsonb_populate_record turns
+// them back into the live table's row type, so columns added later simply
+// read as NULL for older versions; days_past_due reads as 0 for those, and
+// the schedule structure as standard.
+const getLoanAtQuery = `
+        SELECT l.id, l.public_id, l.principal_amount, l.interest_rate, l.term_weeks,
+               l.weekly_payment_amount, l.total_loan_amount, l.start_date,
+               l.status, COALESCE(l.days_past_due, 0), l.external_ref, l.created_at, l.updated_at,
+               COALESCE(l.schedule_type, 'STANDARD'), COALESCE(l.interest_only_weeks, 0), COALESCE(l.balloon_amount, 0)
+        FROM loans_history h
+        CROSS JOIN LATERAL jsonb_populate_record(NULL::loans, h.row_data) AS l
+        WHERE h.loan_id = $1 AND h.valid_from <= $2 AND (h.valid_to IS NULL OR h.valid_to > $2)
+        ORDER BY h.valid_from DESC
+        LIMIT 1`
+
+const getScheduleAtQuery = `
+        SELECT s.id, s.loan_id, s.week_number, s.due_date, s.due_amount, s.paid_amount,
+               s.payment_date, s.status, s.created_at, s.updated_at
+        FROM loan_schedule_history h
+        CROSS JOIN LATERAL jsonb_populate_record(NULL::loan_schedule, h.row_data) AS s
+        WHERE h.loan_id = $1 AND h.valid_from <= $2 AND (h.valid_to IS NULL OR h.valid_to > $2)
+        ORDER BY s.week_number ASC`
+
+func (r *LoanRepository) GetLoanAt(ctx context.Context, loanID int64, at time.Time) (*loan.Loan, error) {
+	startTime := time.Now()
+	status := "success"
+
+	var l loan.Loan
+	err := r.db.QueryRow(ctx, getLoanAtQuery, loanID, at).Scan(
+		&l.ID, &l.PublicID, &l.PrincipalAmount, &l.InterestRate, &l.TermWeeks,
+		&l.WeeklyPaymentAmount, &l.TotalLoanAmount, &l.StartDate,
+		&l.Status, &l.DaysPastDue, &l.ExternalRef, &l.CreatedAt, &l.UpdatedAt,
+		&l.Structure.Type, &l.Structure.InterestOnlyWeeks, &l.Structure.BalloonAmount,
+	)
+	if err != nil {
+		status = "error"
+	}
+	monitoring.RecordDBQuery("GetLoanAt", status, time.Since(startTime))
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.logger.WarnContext(ctx, "No loan version at requested time", "loan_id", loanID, "at", at)
+			return nil, apperrors.ErrNotFound
+		}
+		r.logger.ErrorContext(ctx, "Failed to get loan version", "loan_id", loanID, "at", at, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	return &l, nil
+}
+
+func (r *LoanRepository) GetScheduleAt(ctx context.Context, loanID int64, at time.Time) ([]loan.ScheduleEntry, error) {
+	startTime := time.Now()
+	rows, err := r.db.Query(ctx, getScheduleAtQuery, loanID, at)
+	if err != nil {
+		monitoring.RecordDBQuery("GetScheduleAt", "error", time.Since(startTime))
+		r.logger.ErrorContext(ctx, "Failed to query schedule versions", "loan_id", loanID, "at", at, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	schedule := make([]loan.ScheduleEntry, 0)
+	for rows.Next() {
+		var entry loan.ScheduleEntry
+		err := rows.Scan(
+			&entry.ID, &entry.LoanID, &entry.WeekNumber, &entry.DueDate,
+			&entry.DueAmount, &entry.PaidAmount, &entry.PaymentDate,
+			&entry.Status, &entry.CreatedAt, &entry.UpdatedAt,
+		)
+		if err != nil {
+			r.logger.ErrorContext(ctx, "Failed to scan schedule version row", "loan_id", loanID, "error", err)
+			return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+		}
+		schedule = append(schedule, entry)
+	}
+	if err = rows.Err(); err != nil {
+		r.logger.ErrorContext(ctx, "Error iterating schedule version rows", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+
+	monitoring.RecordDBQuery("GetScheduleAt", "success", time.Since(startTime))
+	return schedule, nil
+}
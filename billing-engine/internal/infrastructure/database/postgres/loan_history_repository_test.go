@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/pkg/apperrors"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var historyAt = time.Date(2025, 3, 31, 12, 0, 0, 0, time.UTC)
+
+func TestLoanRepositoryGetLoanAt(t *testing.T) {
+	t.Run("returns the version valid at the instant", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+
+		publicID := uuid.New()
+		mockPool.ExpectQuery(regexp.QuoteMeta(getLoanAtQuery)).
+			WithArgs(int64(7), historyAt).
+			WillReturnRows(pgxmock.NewRows([]string{
+				"id", "public_id", "principal_amount", "interest_rate", "term_weeks", "weekly_payment_amount",
+				"total_loan_amount", "start_date", "status", "days_past_due", "external_ref", "created_at", "updated_at",
+				"coalesce", "coalesce", "coalesce",
+			}).AddRow(
+				int64(7), publicID, 1000.0, 0.1, 10, 110.0,
+				1100.0, historyAt, loan.StatusDelinquent, 0, (*string)(nil), historyAt, historyAt,
+				loan.ScheduleInterestOnly, 4, 0.0,
+			))
+
+		got, err := repo.GetLoanAt(ctx, 7, historyAt)
+
+		require.NoError(t, err)
+		assert.Equal(t, loan.StatusDelinquent, got.Status)
+		assert.Equal(t, publicID, got.PublicID)
+		assert.Equal(t, loan.ScheduleStructure{Type: loan.ScheduleInterestOnly, InterestOnlyWeeks: 4}, got.Structure)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("not found before the loan existed", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+
+		mockPool.ExpectQuery(regexp.QuoteMeta(getLoanAtQuery)).
+			WithArgs(int64(7), historyAt).
+			WillReturnError(pgx.ErrNoRows)
+
+		got, err := repo.GetLoanAt(ctx, 7, historyAt)
+
+		assert.Nil(t, got)
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+
+		mockPool.ExpectQuery(regexp.QuoteMeta(getLoanAtQuery)).
+			WithArgs(int64(7), historyAt).
+			WillReturnError(errors.New("connection reset"))
+
+		_, err := repo.GetLoanAt(ctx, 7, historyAt)
+
+		assert.ErrorIs(t, err, apperrors.ErrDatabase)
+	})
+}
+
+func TestLoanRepositoryGetScheduleAt(t *testing.T) {
+	ctx, repo, mockPool := setupLoanRepo(t)
+	defer mockPool.Close()
+
+	mockPool.ExpectQuery(regexp.QuoteMeta(getScheduleAtQuery)).
+		WithArgs(int64(7), historyAt).
+		WillReturnRows(pgxmock.NewRows([]string{
+			"id", "loan_id", "week_number", "due_date", "due_amount", "paid_amount",
+			"payment_date", "status", "created_at", "updated_at",
+		}).
+			AddRow(int64(1), int64(7), 1, historyAt, 110.0, 110.0, &historyAt, loan.PaymentStatusPaid, historyAt, historyAt).
+			AddRow(int64(2), int64(7), 2, historyAt, 110.0, 0.0, (*time.Time)(nil), loan.PaymentStatusPending, historyAt, historyAt))
+
+	schedule, err := repo.GetScheduleAt(ctx, 7, historyAt)
+
+	require.NoError(t, err)
+	require.Len(t, schedule, 2)
+	assert.Equal(t, loan.PaymentStatusPaid, schedule[0].Status)
+	assert.Nil(t, schedule[1].PaymentDate)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
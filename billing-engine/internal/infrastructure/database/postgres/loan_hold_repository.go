@@ -0,0 +1,117 @@
+package postgres
+
+import (
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/infrastructure/monitoring"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const holdColumns = `id, loan_id, reason, placed_by, placed_at, released_by, released_at`
+
+const (
+	// waitForLoanPaymentLock takes the lock loanTx.LockLoanForPayment tries, but
+	// waits for it. Once a hold holds it, every payment that started earlier
+	// has committed or rolled back and every later one sees the hold.
+	waitForLoanPaymentLockQuery = `SELECT pg_advisory_xact_lock($1)`
+	placeHoldQuery              = `INSERT INTO loan_holds (loan_id, reason, placed_by, placed_at) VALUES ($1, $2, $3, $4) RETURNING id`
+	releaseHoldQuery            = `
+        UPDATE loan_holds SET released_by = $2, released_at = $3
+        WHERE loan_id = $1 AND released_at IS NULL
+        RETURNING ` + holdColumns
+	getActiveHoldQuery = `SELECT ` + holdColumns + ` FROM loan_holds WHERE loan_id = $1 AND released_at IS NULL`
+)
+
+func holdFields(h *loan.Hold) []any {
+	return []any{&h.ID, &h.LoanID, &h.Reason, &h.PlacedBy, &h.PlacedAt, &h.ReleasedBy, &h.ReleasedAt}
+}
+
+func (r *LoanRepository) PlaceHold(ctx context.Context, hold *loan.Hold) error {
+	start := time.Now()
+	tx, err := r.beginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer r.rollbackTx(ctx, tx)
+
+	if err := r.placeHold(ctx, tx, hold); err != nil {
+		monitoring.RecordDBQuery("PlaceHold", "error", time.Since(start))
+		return err
+	}
+	if err := r.commitTx(ctx, tx); err != nil {
+		monitoring.RecordDBQuery("PlaceHold", "error", time.Since(start))
+		return err
+	}
+	monitoring.RecordDBQuery("PlaceHold", "success", time.Since(start))
+	return nil
+}
+
+func (r *LoanRepository) placeHold(ctx context.Context, tx pgx.Tx, hold *loan.Hold) error {
+	if _, err := tx.Exec(ctx, waitForLoanPaymentLockQuery, hold.LoanID); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to lock loan for hold", "loan_id", hold.LoanID, "error", err)
+		return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	err := tx.QueryRow(ctx, placeHoldQuery, hold.LoanID, hold.Reason, hold.PlacedBy, hold.PlacedAt).Scan(&hold.ID)
+	if err == nil {
+		return nil
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23503":
+			return fmt.Errorf("%w: loan %d", apperrors.ErrNotFound, hold.LoanID)
+		case "23505":
+			return fmt.Errorf("%w: loan %d is already on hold", apperrors.ErrConflict, hold.LoanID)
+		}
+	}
+	r.logger.ErrorContext(ctx, "Failed to insert loan hold", "loan_id", hold.LoanID, "error", err)
+	return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+}
+
+func (r *LoanRepository) ReleaseHold(ctx context.Context, loanID int64, releasedBy *string, releasedAt time.Time) (*loan.Hold, error) {
+	start := time.Now()
+	var h loan.Hold
+	err := r.db.QueryRow(ctx, releaseHoldQuery, loanID, releasedBy, releasedAt).Scan(holdFields(&h)...)
+	if errors.Is(err, pgx.ErrNoRows) {
+		monitoring.RecordDBQuery("ReleaseHold", "not_found", time.Since(start))
+		return nil, fmt.Errorf("%w: loan %d has no open hold", apperrors.ErrNotFound, loanID)
+	}
+	if err != nil {
+		monitoring.RecordDBQuery("ReleaseHold", "error", time.Since(start))
+		r.logger.ErrorContext(ctx, "Failed to release loan hold", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	monitoring.RecordDBQuery("ReleaseHold", "success", time.Since(start))
+	return &h, nil
+}
+
+func (r *LoanRepository) GetActiveHold(ctx context.Context, loanID int64) (*loan.Hold, error) {
+	return r.getActiveHold(ctx, r.db, loanID)
+}
+
+func (t *loanTx) GetActiveHold(ctx context.Context, loanID int64) (*loan.Hold, error) {
+	return t.r.getActiveHold(ctx, t.tx, loanID)
+}
+
+func (r *LoanRepository) getActiveHold(ctx context.Context, db queryRower, loanID int64) (*loan.Hold, error) {
+	start := time.Now()
+	var h loan.Hold
+	err := db.QueryRow(ctx, getActiveHoldQuery, loanID).Scan(holdFields(&h)...)
+	if errors.Is(err, pgx.ErrNoRows) {
+		monitoring.RecordDBQuery("GetActiveHold", "success", time.Since(start))
+		return nil, nil
+	}
+	if err != nil {
+		monitoring.RecordDBQuery("GetActiveHold", "error", time.Since(start))
+		r.logger.ErrorContext(ctx, "Failed to read loan hold", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	monitoring.RecordDBQuery("GetActiveHold", "success", time.Since(start))
+	return &h, nil
+}
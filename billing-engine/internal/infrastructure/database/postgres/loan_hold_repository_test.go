@@ -0,0 +1,112 @@
+package postgres
+
+import (
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/pkg/apperrors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var holdColumnNames = []string{"id", "loan_id", "reason", "placed_by", "placed_at", "released_by", "released_at"}
+
+func TestLoanRepositoryPlaceHold(t *testing.T) {
+	admin := "admin"
+	placedAt := time.Date(2025, 4, 2, 9, 0, 0, 0, time.UTC)
+	newHold := func() *loan.Hold {
+		return &loan.Hold{LoanID: 1, Reason: "disputed", PlacedBy: &admin, PlacedAt: placedAt}
+	}
+
+	t.Run("waits for the payment lock and inserts the hold", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+		mockPool.ExpectBegin()
+		mockPool.ExpectExec(regexp.QuoteMeta(waitForLoanPaymentLockQuery)).WithArgs(int64(1)).
+			WillReturnResult(pgxmock.NewResult("SELECT", 1))
+		mockPool.ExpectQuery(regexp.QuoteMeta(placeHoldQuery)).WithArgs(int64(1), "disputed", &admin, placedAt).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int64(7)))
+		mockPool.ExpectCommit()
+
+		hold := newHold()
+		require.NoError(t, repo.PlaceHold(ctx, hold))
+
+		assert.Equal(t, int64(7), hold.ID)
+		assert.NoError(t, mockPool.ExpectationsWereMet())
+	})
+
+	for _, tc := range []struct {
+		name string
+		code string
+		want error
+	}{
+		{"unknown loan", "23503", apperrors.ErrNotFound},
+		{"hold already open", "23505", apperrors.ErrConflict},
+		{"other database error", "42P01", apperrors.ErrDatabase},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, repo, mockPool := setupLoanRepo(t)
+			defer mockPool.Close()
+			mockPool.ExpectBegin()
+			mockPool.ExpectExec(regexp.QuoteMeta(waitForLoanPaymentLockQuery)).WithArgs(int64(1)).
+				WillReturnResult(pgxmock.NewResult("SELECT", 1))
+			mockPool.ExpectQuery(regexp.QuoteMeta(placeHoldQuery)).WithArgs(int64(1), "disputed", &admin, placedAt).
+				WillReturnError(&pgconn.PgError{Code: tc.code})
+			mockPool.ExpectRollback()
+
+			err := repo.PlaceHold(ctx, newHold())
+
+			assert.ErrorIs(t, err, tc.want)
+			assert.NoError(t, mockPool.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestLoanRepositoryReleaseHold(t *testing.T) {
+	ctx, repo, mockPool := setupLoanRepo(t)
+	defer mockPool.Close()
+	admin := "admin"
+	placedAt := time.Date(2025, 4, 2, 9, 0, 0, 0, time.UTC)
+	releasedAt := placedAt.Add(time.Hour)
+
+	mockPool.ExpectQuery(regexp.QuoteMeta(releaseHoldQuery)).WithArgs(int64(1), &admin, releasedAt).
+		WillReturnRows(pgxmock.NewRows(holdColumnNames).AddRow(int64(7), int64(1), "disputed", &admin, placedAt, &admin, &releasedAt))
+	hold, err := repo.ReleaseHold(ctx, 1, &admin, releasedAt)
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), hold.ID)
+	require.NotNil(t, hold.ReleasedAt)
+	assert.Equal(t, releasedAt, *hold.ReleasedAt)
+
+	mockPool.ExpectQuery(regexp.QuoteMeta(releaseHoldQuery)).WithArgs(int64(1), &admin, releasedAt).
+		WillReturnError(pgx.ErrNoRows)
+	_, err = repo.ReleaseHold(ctx, 1, &admin, releasedAt)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+
+	assert.NoError(t, mockPool.ExpectationsWereMet())
+}
+
+func TestLoanRepositoryGetActiveHold(t *testing.T) {
+	ctx, repo, mockPool := setupLoanRepo(t)
+	defer mockPool.Close()
+	placedAt := time.Date(2025, 4, 2, 9, 0, 0, 0, time.UTC)
+
+	mockPool.ExpectQuery(regexp.QuoteMeta(getActiveHoldQuery)).WithArgs(int64(1)).
+		WillReturnRows(pgxmock.NewRows(holdColumnNames).AddRow(int64(7), int64(1), "disputed", nil, placedAt, nil, nil))
+	hold, err := repo.GetActiveHold(ctx, 1)
+	require.NoError(t, err)
+	require.NotNil(t, hold)
+	assert.Equal(t, "disputed", hold.Reason)
+	assert.Nil(t, hold.PlacedBy)
+
+	mockPool.ExpectQuery(regexp.QuoteMeta(getActiveHoldQuery)).WithArgs(int64(2)).WillReturnError(pgx.ErrNoRows)
+	hold, err = inTx(repo, mockPool).GetActiveHold(ctx, 2)
+	require.NoError(t, err)
+	assert.Nil(t, hold, "a loan without an open hold has none")
+
+	assert.NoError(t, mockPool.ExpectationsWereMet())
+}
@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/infrastructure/monitoring"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"fmt"
+	"time"
+)
+
+const prepaymentColumns = `id, loan_id, option, amount, after_week, principal, weekly_principal,
+        previous_term_weeks, previous_weekly_payment_amount, previous_total_loan_amount,
+        term_weeks, weekly_payment_amount, total_loan_amount, channel, reference, recorded_by, paid_at`
+
+const (
+	getPrepaymentsQuery   = `SELECT ` + prepaymentColumns + ` FROM prepayments WHERE loan_id = $1 ORDER BY id`
+	insertPrepaymentQuery = `
+        INSERT INTO prepayments (loan_id, option, amount, after_week, principal, weekly_principal,
+            previous_term_weeks, previous_weekly_payment_amount, previous_total_loan_amount,
+            term_weeks, weekly_payment_amount, total_loan_amount, channel, reference, recorded_by, paid_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+        RETURNING id`
+	reamortizeLoanQuery = `UPDATE loans SET term_weeks = $1, weekly_payment_amount = $2, total_loan_amount = $3, updated_at = $4 WHERE id = $5`
+)
+
+func (r *LoanRepository) GetPrepayments(ctx context.Context, loanID int64) ([]loan.Prepayment, error) {
+	return r.getPrepayments(ctx, r.db, loanID)
+}
+
+func (t *loanTx) GetPrepayments(ctx context.Context, loanID int64) ([]loan.Prepayment, error) {
+	return t.r.getPrepayments(ctx, t.tx, loanID)
+}
+
+func (r *LoanRepository) getPrepayments(ctx context.Context, db rowsQuerier, loanID int64) ([]loan.Prepayment, error) {
+	start := time.Now()
+	rows, err := db.Query(ctx, getPrepaymentsQuery, loanID)
+	if err != nil {
+		monitoring.RecordDBQuery("GetPrepayments", "error", time.Since(start))
+		r.logger.ErrorContext(ctx, "Failed to read prepayments", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	prepayments := []loan.Prepayment{}
+	for rows.Next() {
+		var p loan.Prepayment
+		if err := rows.Scan(&p.ID, &p.LoanID, &p.Option, &p.Amount, &p.AfterWeek, &p.Principal, &p.WeeklyPrincipal,
+			&p.PreviousTermWeeks, &p.PreviousWeeklyPaymentAmount, &p.PreviousTotalLoanAmount,
+			&p.TermWeeks, &p.WeeklyPaymentAmount, &p.TotalLoanAmount, &p.Channel, &p.Reference, &p.RecordedBy, &p.PaidAt); err != nil {
+			monitoring.RecordDBQuery("GetPrepayments", "error", time.Since(start))
+			r.logger.ErrorContext(ctx, "Failed to scan prepayment", "loan_id", loanID, "error", err)
+			return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+		}
+		prepayments = append(prepayments, p)
+	}
+	if err := rows.Err(); err != nil {
+		monitoring.RecordDBQuery("GetPrepayments", "error", time.Since(start))
+		r.logger.ErrorContext(ctx, "Error iterating prepayments", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	monitoring.RecordDBQuery("GetPrepayments", "success", time.Since(start))
+	return prepayments, nil
+}
+
+func (t *loanTx) RecordPrepayment(ctx context.Context, reamortization *loan.Reamortization) error {
+	p := &reamortization.Prepayment
+	cmdTag, err := t.tx.Exec(ctx, reamortizeLoanQuery, p.TermWeeks, p.WeeklyPaymentAmount, p.TotalLoanAmount, t.r.clock.Now(), p.LoanID)
+	if err != nil {
+		t.r.logger.ErrorContext(ctx, "Failed to reamortize loan", "loan_id", p.LoanID, "error", err)
+		return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	if cmdTag.RowsAffected() != 1 {
+		t.r.logger.ErrorContext(ctx, "Loan reamortization affected zero rows", "loan_id", p.LoanID)
+		return fmt.Errorf("%w: loan reamortization affected zero rows", apperrors.ErrDatabase)
+	}
+
+	err = t.tx.QueryRow(ctx, insertPrepaymentQuery, p.LoanID, p.Option, p.Amount, p.AfterWeek, p.Principal, p.WeeklyPrincipal,
+		p.PreviousTermWeeks, p.PreviousWeeklyPaymentAmount, p.PreviousTotalLoanAmount,
+		p.TermWeeks, p.WeeklyPaymentAmount, p.TotalLoanAmount, p.Channel, p.Reference, p.RecordedBy, p.PaidAt).Scan(&p.ID)
+	if err != nil {
+		return translateDBError(err, t.r.logger.With("loan_id", p.LoanID))
+	}
+	return nil
+}
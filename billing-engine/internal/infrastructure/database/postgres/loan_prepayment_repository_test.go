@@ -0,0 +1,109 @@
+package postgres
+
+import (
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/pkg/apperrors"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var prepaymentColumnNames = []string{"id", "loan_id", "option", "amount", "after_week", "principal", "weekly_principal",
+	"previous_term_weeks", "previous_weekly_payment_amount", "previous_total_loan_amount",
+	"term_weeks", "weekly_payment_amount", "total_loan_amount", "channel", "reference", "recorded_by", "paid_at"}
+
+func TestLoanRepositoryGetPrepayments(t *testing.T) {
+	ctx, repo, mockPool := setupLoanRepo(t)
+	defer mockPool.Close()
+	teller := "teller"
+	reference := "TRX-9"
+
+	mockPool.ExpectQuery(regexp.QuoteMeta(getPrepaymentsQuery)).WithArgs(int64(1)).
+		WillReturnRows(pgxmock.NewRows(prepaymentColumnNames).
+			AddRow(int64(3), int64(1), loan.PrepaymentReduceInstallment, 50.0, 1, 150.0, 75.0, 3, 110.0, 330.0, 3, 82.5, 325.0,
+				loan.ChannelBankTransfer, &reference, &teller, testClock.Now()))
+	prepayments, err := repo.GetPrepayments(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, prepayments, 1)
+	p := prepayments[0]
+	assert.Equal(t, loan.PrepaymentReduceInstallment, p.Option)
+	assert.Equal(t, 75.0, p.WeeklyPrincipal)
+	assert.Equal(t, 82.5, p.WeeklyPaymentAmount)
+	assert.Equal(t, "TRX-9", *p.Reference)
+
+	mockPool.ExpectQuery(regexp.QuoteMeta(getPrepaymentsQuery)).WithArgs(int64(2)).
+		WillReturnRows(pgxmock.NewRows(prepaymentColumnNames))
+	prepayments, err = inTx(repo, mockPool).GetPrepayments(ctx, 2)
+	require.NoError(t, err)
+	assert.NotNil(t, prepayments)
+	assert.Empty(t, prepayments)
+
+	mockPool.ExpectQuery(regexp.QuoteMeta(getPrepaymentsQuery)).WithArgs(int64(3)).
+		WillReturnError(errors.New("connection reset"))
+	_, err = repo.GetPrepayments(ctx, 3)
+	assert.ErrorIs(t, err, apperrors.ErrDatabase)
+
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestLoanRepositoryRecordPrepayment(t *testing.T) {
+	teller := "teller"
+	reference := "TRX-9"
+	newReamortization := func() *loan.Reamortization {
+		return &loan.Reamortization{Prepayment: loan.Prepayment{
+			LoanID: 1, Option: loan.PrepaymentReduceTerm, Amount: 100, AfterWeek: 1, Principal: 100, WeeklyPrincipal: 100,
+			PreviousTermWeeks: 3, PreviousWeeklyPaymentAmount: 110, PreviousTotalLoanAmount: 330,
+			TermWeeks: 2, WeeklyPaymentAmount: 110, TotalLoanAmount: 320,
+			Channel: loan.ChannelBankTransfer, Reference: &reference, RecordedBy: &teller, PaidAt: testClock.Now(),
+		}}
+	}
+	expectInsert := func(mockPool pgxmock.PgxPoolIface) *pgxmock.ExpectedQuery {
+		return mockPool.ExpectQuery(regexp.QuoteMeta(insertPrepaymentQuery)).
+			WithArgs(int64(1), loan.PrepaymentReduceTerm, 100.0, 1, 100.0, 100.0, 3, 110.0, 330.0, 2, 110.0, 320.0,
+				loan.ChannelBankTransfer, &reference, &teller, testClock.Now())
+	}
+
+	t.Run("stores the new terms and the prepayment", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+		mockPool.ExpectExec(regexp.QuoteMeta(reamortizeLoanQuery)).WithArgs(2, 110.0, 320.0, testClock.Now(), int64(1)).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		expectInsert(mockPool).WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int64(6)))
+		reamortization := newReamortization()
+
+		require.NoError(t, inTx(repo, mockPool).RecordPrepayment(ctx, reamortization))
+
+		assert.Equal(t, int64(6), reamortization.Prepayment.ID)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("reports a reference already posted", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+		mockPool.ExpectExec(regexp.QuoteMeta(reamortizeLoanQuery)).WithArgs(2, 110.0, 320.0, testClock.Now(), int64(1)).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		expectInsert(mockPool).WillReturnError(&pgconn.PgError{Code: "23505", ConstraintName: "uq_prepayments_channel_reference"})
+
+		err := inTx(repo, mockPool).RecordPrepayment(ctx, newReamortization())
+
+		assert.ErrorIs(t, err, apperrors.ErrAlreadyExists)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("fails when the loan is gone", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+		mockPool.ExpectExec(regexp.QuoteMeta(reamortizeLoanQuery)).WithArgs(2, 110.0, 320.0, testClock.Now(), int64(1)).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+		err := inTx(repo, mockPool).RecordPrepayment(ctx, newReamortization())
+
+		assert.ErrorIs(t, err, apperrors.ErrDatabase)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+}
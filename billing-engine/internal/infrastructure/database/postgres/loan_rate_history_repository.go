@@ -0,0 +1,89 @@
+package postgres
+
+import (
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/infrastructure/monitoring"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const rateChangeColumns = `id, loan_id, previous_rate, rate, effective_from, changed_by, created_at`
+
+const (
+	getRateHistoryQuery   = `SELECT ` + rateChangeColumns + ` FROM rate_history WHERE loan_id = $1 ORDER BY effective_from, id`
+	insertRateChangeQuery = `
+        INSERT INTO rate_history (loan_id, previous_rate, rate, effective_from, changed_by, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING id`
+	repriceLoanQuery = `
+        UPDATE loans SET interest_rate = $1, weekly_payment_amount = $2, total_loan_amount = $3, updated_at = $4
+        WHERE id = $5`
+)
+
+// rowsQuerier is what reading the rate history needs from a pool or a
+// transaction.
+type rowsQuerier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+func (r *LoanRepository) GetRateHistory(ctx context.Context, loanID int64) ([]loan.RateChange, error) {
+	return r.getRateHistory(ctx, r.db, loanID)
+}
+
+func (t *loanTx) GetRateHistory(ctx context.Context, loanID int64) ([]loan.RateChange, error) {
+	return t.r.getRateHistory(ctx, t.tx, loanID)
+}
+
+func (r *LoanRepository) getRateHistory(ctx context.Context, db rowsQuerier, loanID int64) ([]loan.RateChange, error) {
+	start := time.Now()
+	rows, err := db.Query(ctx, getRateHistoryQuery, loanID)
+	if err != nil {
+		monitoring.RecordDBQuery("GetRateHistory", "error", time.Since(start))
+		r.logger.ErrorContext(ctx, "Failed to read rate history", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	history := []loan.RateChange{}
+	for rows.Next() {
+		var c loan.RateChange
+		if err := rows.Scan(&c.ID, &c.LoanID, &c.PreviousRate, &c.Rate, &c.EffectiveFrom, &c.ChangedBy, &c.CreatedAt); err != nil {
+			monitoring.RecordDBQuery("GetRateHistory", "error", time.Since(start))
+			r.logger.ErrorContext(ctx, "Failed to scan rate change", "loan_id", loanID, "error", err)
+			return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+		}
+		history = append(history, c)
+	}
+	if err := rows.Err(); err != nil {
+		monitoring.RecordDBQuery("GetRateHistory", "error", time.Since(start))
+		r.logger.ErrorContext(ctx, "Error iterating rate history", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	monitoring.RecordDBQuery("GetRateHistory", "success", time.Since(start))
+	return history, nil
+}
+
+func (t *loanTx) RepriceLoan(ctx context.Context, repricing *loan.Repricing) error {
+	change := &repricing.Change
+	cmdTag, err := t.tx.Exec(ctx, repriceLoanQuery, change.Rate, repricing.WeeklyPaymentAmount, repricing.TotalLoanAmount, t.r.clock.Now(), change.LoanID)
+	if err != nil {
+		t.r.logger.ErrorContext(ctx, "Failed to update loan terms", "loan_id", change.LoanID, "error", err)
+		return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	if cmdTag.RowsAffected() != 1 {
+		t.r.logger.ErrorContext(ctx, "Loan repricing affected zero rows", "loan_id", change.LoanID)
+		return fmt.Errorf("%w: loan repricing affected zero rows", apperrors.ErrDatabase)
+	}
+	err = t.tx.QueryRow(ctx, insertRateChangeQuery, change.LoanID, change.PreviousRate, change.Rate, change.EffectiveFrom,
+		change.ChangedBy, change.CreatedAt).Scan(&change.ID)
+	if err != nil {
+		t.r.logger.ErrorContext(ctx, "Failed to insert rate change", "loan_id", change.LoanID, "error", err)
+		return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	t.r.logger.InfoContext(ctx, "Loan repriced in DB", "loan_id", change.LoanID, "rate", change.Rate)
+	return nil
+}
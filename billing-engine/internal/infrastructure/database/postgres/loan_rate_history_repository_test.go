@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/pkg/apperrors"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var rateChangeColumnNames = []string{"id", "loan_id", "previous_rate", "rate", "effective_from", "changed_by", "created_at"}
+
+func TestLoanRepositoryGetRateHistory(t *testing.T) {
+	ctx, repo, mockPool := setupLoanRepo(t)
+	defer mockPool.Close()
+	admin := "admin"
+	effectiveFrom := time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC)
+
+	mockPool.ExpectQuery(regexp.QuoteMeta(getRateHistoryQuery)).WithArgs(int64(1)).
+		WillReturnRows(pgxmock.NewRows(rateChangeColumnNames).AddRow(int64(3), int64(1), 0.1, 0.16, effectiveFrom, &admin, testClock.Now()))
+	history, err := repo.GetRateHistory(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, 0.16, history[0].Rate)
+	assert.Equal(t, effectiveFrom, history[0].EffectiveFrom)
+
+	mockPool.ExpectQuery(regexp.QuoteMeta(getRateHistoryQuery)).WithArgs(int64(2)).
+		WillReturnRows(pgxmock.NewRows(rateChangeColumnNames))
+	history, err = inTx(repo, mockPool).GetRateHistory(ctx, 2)
+	require.NoError(t, err)
+	assert.NotNil(t, history)
+	assert.Empty(t, history)
+
+	mockPool.ExpectQuery(regexp.QuoteMeta(getRateHistoryQuery)).WithArgs(int64(3)).
+		WillReturnError(errors.New("connection reset"))
+	_, err = repo.GetRateHistory(ctx, 3)
+	assert.ErrorIs(t, err, apperrors.ErrDatabase)
+
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestLoanRepositoryRepriceLoan(t *testing.T) {
+	admin := "admin"
+	newRepricing := func() *loan.Repricing {
+		return &loan.Repricing{
+			Change: loan.RateChange{
+				LoanID: 1, PreviousRate: 0.1, Rate: 0.16,
+				EffectiveFrom: time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC), ChangedBy: &admin, CreatedAt: testClock.Now(),
+			},
+			WeeklyPaymentAmount: 116,
+			TotalLoanAmount:     342,
+		}
+	}
+
+	t.Run("updates the terms and records the change", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+		r := newRepricing()
+		mockPool.ExpectExec(regexp.QuoteMeta(repriceLoanQuery)).WithArgs(0.16, 116.0, 342.0, testClock.Now(), int64(1)).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mockPool.ExpectQuery(regexp.QuoteMeta(insertRateChangeQuery)).
+			WithArgs(int64(1), 0.1, 0.16, r.Change.EffectiveFrom, &admin, testClock.Now()).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int64(4)))
+
+		require.NoError(t, inTx(repo, mockPool).RepriceLoan(ctx, r))
+
+		assert.Equal(t, int64(4), r.Change.ID)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("fails when the loan is gone", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+		mockPool.ExpectExec(regexp.QuoteMeta(repriceLoanQuery)).WithArgs(0.16, 116.0, 342.0, testClock.Now(), int64(1)).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+		err := inTx(repo, mockPool).RepriceLoan(ctx, newRepricing())
+
+		assert.ErrorIs(t, err, apperrors.ErrDatabase)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+}
@@ -2,14 +2,18 @@ package postgres
 
 import (
 	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/infrastructure/database/sqlbuilder"
 	"billing-engine/internal/infrastructure/monitoring"
 	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"billing-engine/internal/pkg/scope"
 	"context"
 	"errors"
 	"fmt"
 	"log/slog"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -21,12 +25,14 @@ type DBPool interface {
 	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
 	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
 	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
 	Acquire(ctx context.Context) (*pgxpool.Conn, error)
 	Close()
 }
 
 type LoanRepository struct {
 	db     DBPool
+	clock  clock.Clock
 	logger *slog.Logger
 }
 
@@ -36,11 +42,14 @@ var _ DBPool = (pgxmock.PgxPoolIface)(nil)
 
 var errMsgFormat = "%w: %w"
 
-func NewLoanRepository(db DBPool, logger *slog.Logger) *LoanRepository {
-	return &LoanRepository{db: db, logger: logger.With("component", "LoanRepository")}
+// NewLoanRepository builds the loan repository. Timestamps it writes and the
+// due date cut-off for delinquency come from clk rather than NOW(); nil
+// means the wall clock.
+func NewLoanRepository(db DBPool, clk clock.Clock, logger *slog.Logger) *LoanRepository {
+	return &LoanRepository{db: db, clock: clock.OrSystem(clk), logger: logger.With("component", "LoanRepository")}
 }
 
-func (r *LoanRepository) BeginTx(ctx context.Context) (pgx.Tx, error) {
+func (r *LoanRepository) beginTx(ctx context.Context) (pgx.Tx, error) {
 	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		r.logger.ErrorContext(ctx, "Failed to begin transaction", "error", err)
@@ -49,49 +58,94 @@ func (r *LoanRepository) BeginTx(ctx context.Context) (pgx.Tx, error) {
 	return tx, nil
 }
 
-func (r *LoanRepository) CommitTx(ctx context.Context, tx pgx.Tx) error {
-	err := tx.Commit(ctx)
-	if err != nil {
-
+func (r *LoanRepository) commitTx(ctx context.Context, tx pgx.Tx) error {
+	if err := tx.Commit(ctx); err != nil {
 		r.logger.ErrorContext(ctx, "Failed to commit transaction", "error", err)
 		return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
 	}
 	return nil
 }
 
-func (r *LoanRepository) RollbackTx(ctx context.Context, tx pgx.Tx) error {
-	err := tx.Rollback(ctx)
-
-	if err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+// rollbackTx is deferred after beginTx, so a transaction that has already
+// been committed is not an error.
+func (r *LoanRepository) rollbackTx(ctx context.Context, tx pgx.Tx) {
+	if err := tx.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
 		r.logger.ErrorContext(ctx, "Failed to rollback transaction", "error", err)
+	}
+}
 
-		return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+// WithinTransaction runs fn against a loanTx and commits when fn returns nil.
+// The deferred rollback also covers a panic in fn.
+func (r *LoanRepository) WithinTransaction(ctx context.Context, fn func(tx loan.TxRepository) error) error {
+	tx, err := r.beginTx(ctx)
+	if err != nil {
+		return err
 	}
-	return nil
+	defer r.rollbackTx(ctx, tx)
+
+	if err := fn(&loanTx{r: r, tx: tx}); err != nil {
+		return err
+	}
+	return r.commitTx(ctx, tx)
+}
+
+// loanTx is the loan.TxRepository handed to WithinTransaction callbacks. Its
+// statements run on tx and share the repository's clock and logger.
+type loanTx struct {
+	r  *LoanRepository
+	tx pgx.Tx
 }
 
+var _ loan.TxRepository = (*loanTx)(nil)
+
+// linkLoanToCustomerQuery gives the customer the new loan in the transaction
+// that creates it. It matches no row when the customer is inactive, belongs
+// to another tenant than the loan or still has a loan that is not paid off,
+// which includes a loan linked by a concurrent create that committed first.
+const linkLoanToCustomerQuery = `
+        UPDATE customers
+        SET loan_id = $1, updated_at = $2
+        WHERE id = $3 AND active AND tenant_id = $4
+          AND (loan_id IS NULL OR loan_id IN (SELECT id FROM loans WHERE status = 'PAID_OFF'))`
+
+// CreateLoan inserts the loan and its schedule and links the loan to the
+// customer in one transaction; if the customer cannot be linked nothing is
+// kept and ErrConflict is returned. The loan belongs to ctx's tenant.
 func (r *LoanRepository) CreateLoan(ctx context.Context, customerID int64, newLoan *loan.Loan, schedule []loan.ScheduleEntry) (*loan.Loan, error) {
-	tx, err := r.BeginTx(ctx)
+	tx, err := r.beginTx(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer r.RollbackTx(ctx, tx)
+	defer r.rollbackTx(ctx, tx)
 
 	loanSQL := `
-        INSERT INTO loans (principal_amount, interest_rate, term_weeks, weekly_payment_amount, total_loan_amount, start_date, status, created_at, updated_at)
-        VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
-        RETURNING id, principal_amount, interest_rate, term_weeks, weekly_payment_amount, total_loan_amount, start_date, status, created_at, updated_at`
+        INSERT INTO loans (public_id, principal_amount, interest_rate, term_weeks, weekly_payment_amount, total_loan_amount, start_date, status, external_ref, created_at, updated_at,
+                           schedule_type, interest_only_weeks, balloon_amount, tenant_id)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $10, $11, $12, $13, $14)
+        RETURNING id, public_id, principal_amount, interest_rate, term_weeks, weekly_payment_amount, total_loan_amount, start_date, status, days_past_due, external_ref, created_at, updated_at,
+                  schedule_type, interest_only_weeks, balloon_amount`
 
+	if newLoan.PublicID == uuid.Nil {
+		newLoan.PublicID = uuid.New()
+	}
+
+	now := r.clock.Now()
+	tenantID := scope.TenantOrDefault(ctx)
 	var createdLoan loan.Loan
 	err = tx.QueryRow(ctx, loanSQL,
-		newLoan.PrincipalAmount, newLoan.InterestRate, newLoan.TermWeeks, newLoan.WeeklyPaymentAmount,
-		newLoan.TotalLoanAmount, newLoan.StartDate, newLoan.Status,
+		newLoan.PublicID, newLoan.PrincipalAmount, newLoan.InterestRate, newLoan.TermWeeks, newLoan.WeeklyPaymentAmount,
+		newLoan.TotalLoanAmount, newLoan.StartDate, newLoan.Status, newLoan.ExternalRef, now,
+		newLoan.Structure.Kind(), newLoan.Structure.InterestOnlyWeeks, newLoan.Structure.BalloonAmount, tenantID,
 	).Scan(
-		&createdLoan.ID, &createdLoan.PrincipalAmount, &createdLoan.InterestRate, &createdLoan.TermWeeks,
+		&createdLoan.ID, &createdLoan.PublicID, &createdLoan.PrincipalAmount, &createdLoan.InterestRate, &createdLoan.TermWeeks,
 		&createdLoan.WeeklyPaymentAmount, &createdLoan.TotalLoanAmount, &createdLoan.StartDate,
-		&createdLoan.Status, &createdLoan.CreatedAt, &createdLoan.UpdatedAt,
+		&createdLoan.Status, &createdLoan.DaysPastDue, &createdLoan.ExternalRef, &createdLoan.CreatedAt, &createdLoan.UpdatedAt,
+		&createdLoan.Structure.Type, &createdLoan.Structure.InterestOnlyWeeks, &createdLoan.Structure.BalloonAmount,
 	)
 	if err != nil {
+		if translated := translateDBError(err, r.logger); errors.Is(translated, apperrors.ErrAlreadyExists) {
+			return nil, translated
+		}
 		r.logger.ErrorContext(ctx, "Failed to insert loan", "error", err)
 
 		return nil, fmt.Errorf("%w: failed to insert loan: %w", apperrors.ErrDatabase, err)
@@ -101,11 +155,11 @@ func (r *LoanRepository) CreateLoan(ctx context.Context, customerID int64, newLo
 	if len(schedule) > 0 {
 		scheduleSQL := `
             INSERT INTO loan_schedule (loan_id, week_number, due_date, due_amount, status, created_at, updated_at)
-            VALUES ($1, $2, $3, $4, $5, NOW(), NOW())`
+            VALUES ($1, $2, $3, $4, $5, $6, $6)`
 
 		batch := &pgx.Batch{}
 		for _, entry := range schedule {
-			batch.Queue(scheduleSQL, createdLoan.ID, entry.WeekNumber, entry.DueDate, entry.DueAmount, entry.Status)
+			batch.Queue(scheduleSQL, createdLoan.ID, entry.WeekNumber, entry.DueDate, entry.DueAmount, entry.Status, now)
 		}
 
 		results := tx.SendBatch(ctx, batch)
@@ -125,45 +179,45 @@ func (r *LoanRepository) CreateLoan(ctx context.Context, customerID int64, newLo
 		}
 	}
 	r.logger.InfoContext(ctx, "Loan schedule created in DB", "loan_id", createdLoan.ID, "num_entries", len(schedule))
+	// The columns were returned before the schedule's trigger filled them in.
+	createdLoan.SummarizeInstallments(schedule)
 
-	r.logger.Info("Updating customer record to link loan")
-	updateCustomerSQL := `
-        UPDATE customers
-        SET loan_id = $1, updated_at = NOW()
-        WHERE id = $2 AND loan_id IS NULL`
-
-	cmdTag, err := tx.Exec(ctx, updateCustomerSQL, createdLoan.ID, customerID)
-	if err := r.CommitTx(ctx, tx); err != nil {
-		return nil, err
-	}
-
+	cmdTag, err := tx.Exec(ctx, linkLoanToCustomerQuery, createdLoan.ID, now, customerID, tenantID)
 	if err != nil {
 		r.logger.ErrorContext(ctx, "Failed to update customer with loan ID", slog.Any("error", err))
-		err = fmt.Errorf("%w: failed to link loan to customer: %w", apperrors.ErrDatabase, err)
-		return nil, err // Trigger rollback
+		return nil, fmt.Errorf("%w: failed to link loan to customer: %w", apperrors.ErrDatabase, err)
 	}
 	if cmdTag.RowsAffected() == 0 {
-		r.logger.Error("Failed to link loan to customer: customer not found or already has a loan ID", slog.Int64("customerID", customerID))
-		err = fmt.Errorf("%w: failed to link loan, customer %d not found or already linked", apperrors.ErrConflict, customerID)
-		return nil, err
+		r.logger.WarnContext(ctx, "Customer cannot take the loan: not found in the tenant, inactive or holding an active loan", slog.Int64("customerID", customerID))
+		return nil, fmt.Errorf("%w: customer %d is not found, inactive or already has an active loan", apperrors.ErrConflict, customerID)
 	}
 
+	if err := r.commitTx(ctx, tx); err != nil {
+		return nil, err
+	}
 	return &createdLoan, nil
 }
 
-func (r *LoanRepository) GetLoanByID(ctx context.Context, loanID int64) (*loan.Loan, error) {
-	query := `
-        SELECT id, principal_amount, interest_rate, term_weeks, weekly_payment_amount, total_loan_amount, start_date, status, created_at, updated_at
+// getLoanByIDQuery and the other single-loan reads end in their WHERE
+// clause, so that sqlbuilder.ScopeToTenant can add the caller's tenant.
+const getLoanByIDQuery = `
+        SELECT id, public_id, principal_amount, interest_rate, term_weeks, weekly_payment_amount, total_loan_amount, start_date, status, days_past_due, external_ref, created_at, updated_at,
+               next_due_date, next_due_amount, installments_paid, installments_remaining, schedule_type, interest_only_weeks, balloon_amount
         FROM loans
         WHERE id = $1`
+
+func (r *LoanRepository) GetLoanByID(ctx context.Context, loanID int64) (*loan.Loan, error) {
 	status := "success"
 	startTime := time.Now()
 
+	query, args := sqlbuilder.ScopeToTenant(ctx, getLoanByIDQuery, "tenant_id", loanID)
 	var l loan.Loan
-	err := r.db.QueryRow(ctx, query, loanID).Scan(
-		&l.ID, &l.PrincipalAmount, &l.InterestRate, &l.TermWeeks,
+	err := r.db.QueryRow(ctx, query, args...).Scan(
+		&l.ID, &l.PublicID, &l.PrincipalAmount, &l.InterestRate, &l.TermWeeks,
 		&l.WeeklyPaymentAmount, &l.TotalLoanAmount, &l.StartDate,
-		&l.Status, &l.CreatedAt, &l.UpdatedAt,
+		&l.Status, &l.DaysPastDue, &l.ExternalRef, &l.CreatedAt, &l.UpdatedAt,
+		&l.NextDueDate, &l.NextDueAmount, &l.InstallmentsPaid, &l.InstallmentsRemaining,
+		&l.Structure.Type, &l.Structure.InterestOnlyWeeks, &l.Structure.BalloonAmount,
 	)
 
 	if err != nil {
@@ -182,18 +236,148 @@ func (r *LoanRepository) GetLoanByID(ctx context.Context, loanID int64) (*loan.L
 	return &l, nil
 }
 
-func (r *LoanRepository) GetScheduleByLoanID(ctx context.Context, loanID int64) ([]loan.ScheduleEntry, error) {
+func (r *LoanRepository) GetLoanByPublicID(ctx context.Context, publicID uuid.UUID) (*loan.Loan, error) {
+	query := `
+        SELECT id, public_id, principal_amount, interest_rate, term_weeks, weekly_payment_amount, total_loan_amount, start_date, status, days_past_due, external_ref, created_at, updated_at,
+               next_due_date, next_due_amount, installments_paid, installments_remaining, schedule_type, interest_only_weeks, balloon_amount
+        FROM loans
+        WHERE public_id = $1`
+	status := "success"
+	startTime := time.Now()
+
+	query, args := sqlbuilder.ScopeToTenant(ctx, query, "tenant_id", publicID)
+	var l loan.Loan
+	err := r.db.QueryRow(ctx, query, args...).Scan(
+		&l.ID, &l.PublicID, &l.PrincipalAmount, &l.InterestRate, &l.TermWeeks,
+		&l.WeeklyPaymentAmount, &l.TotalLoanAmount, &l.StartDate,
+		&l.Status, &l.DaysPastDue, &l.ExternalRef, &l.CreatedAt, &l.UpdatedAt,
+		&l.NextDueDate, &l.NextDueAmount, &l.InstallmentsPaid, &l.InstallmentsRemaining,
+		&l.Structure.Type, &l.Structure.InterestOnlyWeeks, &l.Structure.BalloonAmount,
+	)
+
+	if err != nil {
+		status = "error"
+	}
+	monitoring.RecordDBQuery("GetLoanByPublicID", status, time.Since(startTime))
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.logger.WarnContext(ctx, "Loan not found for public ID")
+			return nil, apperrors.ErrNotFound
+		}
+		r.logger.ErrorContext(ctx, "Failed to get loan by public ID", "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	return &l, nil
+}
+
+func (r *LoanRepository) GetLoanByExternalRef(ctx context.Context, externalRef string) (*loan.Loan, error) {
 	query := `
+        SELECT id, public_id, principal_amount, interest_rate, term_weeks, weekly_payment_amount, total_loan_amount, start_date, status, days_past_due, external_ref, created_at, updated_at,
+               next_due_date, next_due_amount, installments_paid, installments_remaining, schedule_type, interest_only_weeks, balloon_amount
+        FROM loans
+        WHERE external_ref = $1`
+	status := "success"
+	startTime := time.Now()
+
+	query, args := sqlbuilder.ScopeToTenant(ctx, query, "tenant_id", externalRef)
+	var l loan.Loan
+	err := r.db.QueryRow(ctx, query, args...).Scan(
+		&l.ID, &l.PublicID, &l.PrincipalAmount, &l.InterestRate, &l.TermWeeks,
+		&l.WeeklyPaymentAmount, &l.TotalLoanAmount, &l.StartDate,
+		&l.Status, &l.DaysPastDue, &l.ExternalRef, &l.CreatedAt, &l.UpdatedAt,
+		&l.NextDueDate, &l.NextDueAmount, &l.InstallmentsPaid, &l.InstallmentsRemaining,
+		&l.Structure.Type, &l.Structure.InterestOnlyWeeks, &l.Structure.BalloonAmount,
+	)
+
+	if err != nil {
+		status = "error"
+	}
+	monitoring.RecordDBQuery("GetLoanByExternalRef", status, time.Since(startTime))
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.logger.WarnContext(ctx, "Loan not found for external reference")
+			return nil, apperrors.ErrNotFound
+		}
+		r.logger.ErrorContext(ctx, "Failed to get loan by external reference", "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	return &l, nil
+}
+
+// GetLoanWithSchedule reads the loan and its schedule in a single round trip,
+// batching the two queries.
+func (r *LoanRepository) GetLoanWithSchedule(ctx context.Context, loanID int64) (*loan.Loan, error) {
+	status := "success"
+	startTime := time.Now()
+
+	batch := &pgx.Batch{}
+	loanQuery, loanArgs := sqlbuilder.ScopeToTenant(ctx, getLoanByIDQuery, "tenant_id", loanID)
+	batch.Queue(loanQuery, loanArgs...)
+	batch.Queue(getScheduleByLoanIDQuery, loanID)
+	results := r.db.SendBatch(ctx, batch)
+
+	l, err := r.readLoanWithSchedule(ctx, loanID, results)
+	if closeErr := results.Close(); err == nil && closeErr != nil {
+		r.logger.ErrorContext(ctx, "Failed to close loan batch", "loan_id", loanID, "error", closeErr)
+		l, err = nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, closeErr)
+	}
+
+	if err != nil && !errors.Is(err, apperrors.ErrNotFound) {
+		status = "error"
+	}
+	monitoring.RecordDBQuery("GetLoanWithSchedule", status, time.Since(startTime))
+	return l, err
+}
+
+func (r *LoanRepository) readLoanWithSchedule(ctx context.Context, loanID int64, results pgx.BatchResults) (*loan.Loan, error) {
+	var l loan.Loan
+	err := results.QueryRow().Scan(
+		&l.ID, &l.PublicID, &l.PrincipalAmount, &l.InterestRate, &l.TermWeeks,
+		&l.WeeklyPaymentAmount, &l.TotalLoanAmount, &l.StartDate,
+		&l.Status, &l.DaysPastDue, &l.ExternalRef, &l.CreatedAt, &l.UpdatedAt,
+		&l.NextDueDate, &l.NextDueAmount, &l.InstallmentsPaid, &l.InstallmentsRemaining,
+		&l.Structure.Type, &l.Structure.InterestOnlyWeeks, &l.Structure.BalloonAmount,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.logger.WarnContext(ctx, "Loan not found", "loan_id", loanID)
+			return nil, apperrors.ErrNotFound
+		}
+		r.logger.ErrorContext(ctx, "Failed to get loan by ID", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+
+	rows, err := results.Query()
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query loan schedule", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	l.Schedule, err = r.scanSchedule(ctx, loanID, rows)
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+const getScheduleByLoanIDQuery = `
         SELECT id, loan_id, week_number, due_date, due_amount, paid_amount, payment_date, status, created_at, updated_at
         FROM loan_schedule
         WHERE loan_id = $1
         ORDER BY week_number ASC`
 
-	rows, err := r.db.Query(ctx, query, loanID)
+func (r *LoanRepository) GetScheduleByLoanID(ctx context.Context, loanID int64) ([]loan.ScheduleEntry, error) {
+	rows, err := r.db.Query(ctx, getScheduleByLoanIDQuery, loanID)
 	if err != nil {
 		r.logger.ErrorContext(ctx, "Failed to query loan schedule", "loan_id", loanID, "error", err)
 		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
 	}
+	return r.scanSchedule(ctx, loanID, rows)
+}
+
+// scanSchedule reads the rows of getScheduleByLoanIDQuery and closes them.
+func (r *LoanRepository) scanSchedule(ctx context.Context, loanID int64, rows pgx.Rows) ([]loan.ScheduleEntry, error) {
 	defer rows.Close()
 
 	schedule := make([]loan.ScheduleEntry, 0)
@@ -211,7 +395,7 @@ func (r *LoanRepository) GetScheduleByLoanID(ctx context.Context, loanID int64)
 		schedule = append(schedule, entry)
 	}
 
-	if err = rows.Err(); err != nil {
+	if err := rows.Err(); err != nil {
 		r.logger.ErrorContext(ctx, "Error iterating schedule rows", "loan_id", loanID, "error", err)
 		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
 	}
@@ -261,11 +445,11 @@ func (r *LoanRepository) GetLastTwoDueUnpaidSchedules(ctx context.Context, loanI
         SELECT id, loan_id, week_number, due_date, due_amount, paid_amount, payment_date, status, created_at, updated_at
         FROM loan_schedule
         WHERE loan_id = $1 AND status not in ('PAID')
-		AND due_date < NOW()
+		AND due_date < $2
         ORDER BY due_date DESC
         LIMIT 2`
 
-	rows, err := r.db.Query(ctx, query, loanID)
+	rows, err := r.db.Query(ctx, query, loanID, r.clock.Now())
 	if err != nil {
 		r.logger.ErrorContext(ctx, "Failed to query last two unpaid schedules", "loan_id", loanID, "error", err)
 		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
@@ -295,8 +479,25 @@ func (r *LoanRepository) GetLastTwoDueUnpaidSchedules(ctx context.Context, loanI
 	return schedule, nil
 }
 
-func (r *LoanRepository) FindOldestUnpaidEntryForUpdate(ctx context.Context, tx pgx.Tx, loanID int64) (*loan.ScheduleEntry, error) {
+// lockLoanForPaymentQuery takes a transaction-scoped advisory lock keyed by
+// the loan ID. Nothing else in the schema takes advisory locks, so the ID
+// needs no namespace.
+const lockLoanForPaymentQuery = `SELECT pg_try_advisory_xact_lock($1)`
 
+func (t *loanTx) LockLoanForPayment(ctx context.Context, loanID int64) error {
+	var locked bool
+	if err := t.tx.QueryRow(ctx, lockLoanForPaymentQuery, loanID).Scan(&locked); err != nil {
+		t.r.logger.ErrorContext(ctx, "Failed to lock loan for payment", "loan_id", loanID, "error", err)
+		return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	if !locked {
+		t.r.logger.WarnContext(ctx, "Another payment holds the loan lock", "loan_id", loanID)
+		return fmt.Errorf("%w: another payment for loan %d is in progress", apperrors.ErrConflict, loanID)
+	}
+	return nil
+}
+
+func (t *loanTx) FindOldestUnpaidEntryForUpdate(ctx context.Context, loanID int64) (*loan.ScheduleEntry, error) {
 	query := `
         SELECT id, loan_id, week_number, due_date, due_amount, paid_amount, payment_date, status, created_at, updated_at
         FROM loan_schedule
@@ -306,7 +507,7 @@ func (r *LoanRepository) FindOldestUnpaidEntryForUpdate(ctx context.Context, tx
         FOR UPDATE`
 
 	var entry loan.ScheduleEntry
-	err := tx.QueryRow(ctx, query, loanID).Scan(
+	err := t.tx.QueryRow(ctx, query, loanID).Scan(
 		&entry.ID, &entry.LoanID, &entry.WeekNumber, &entry.DueDate,
 		&entry.DueAmount, &entry.PaidAmount, &entry.PaymentDate,
 		&entry.Status, &entry.CreatedAt, &entry.UpdatedAt,
@@ -315,83 +516,316 @@ func (r *LoanRepository) FindOldestUnpaidEntryForUpdate(ctx context.Context, tx
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 
-			r.logger.InfoContext(ctx, "No pending schedule entry found for update", "loan_id", loanID)
+			t.r.logger.InfoContext(ctx, "No pending schedule entry found for update", "loan_id", loanID)
 			return nil, apperrors.ErrNotFound
 		}
-		r.logger.ErrorContext(ctx, "Failed to find/lock oldest unpaid schedule entry", "loan_id", loanID, "error", err)
+		t.r.logger.ErrorContext(ctx, "Failed to find/lock oldest unpaid schedule entry", "loan_id", loanID, "error", err)
 		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
 	}
 	return &entry, nil
 }
 
-func (r *LoanRepository) UpdateScheduleEntryInTx(ctx context.Context, tx pgx.Tx, entry *loan.ScheduleEntry) error {
+func (t *loanTx) UpdateScheduleEntry(ctx context.Context, entry *loan.ScheduleEntry) error {
 	sql := `
         UPDATE loan_schedule
-        SET paid_amount = $1, payment_date = $2, status = $3, updated_at = NOW()
-        WHERE id = $4 AND loan_id = $5`
+        SET paid_amount = $1, payment_date = $2, status = $3, updated_at = $4
+        WHERE id = $5 AND loan_id = $6`
 
-	cmdTag, err := tx.Exec(ctx, sql, entry.PaidAmount, entry.PaymentDate, entry.Status, entry.ID, entry.LoanID)
+	now := t.r.clock.Now()
+	cmdTag, err := t.tx.Exec(ctx, sql, entry.PaidAmount, entry.PaymentDate, entry.Status, now, entry.ID, entry.LoanID)
 	if err != nil {
-		r.logger.ErrorContext(ctx, "Failed to update schedule entry", "entry_id", entry.ID, "loan_id", entry.LoanID, "error", err)
+		t.r.logger.ErrorContext(ctx, "Failed to update schedule entry", "entry_id", entry.ID, "loan_id", entry.LoanID, "error", err)
 		return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
 	}
 	if cmdTag.RowsAffected() != 1 {
-		r.logger.ErrorContext(ctx, "Schedule entry update affected zero rows", "entry_id", entry.ID, "loan_id", entry.LoanID)
+		t.r.logger.ErrorContext(ctx, "Schedule entry update affected zero rows", "entry_id", entry.ID, "loan_id", entry.LoanID)
 
 		return fmt.Errorf("%w: schedule entry update affected zero rows", apperrors.ErrDatabase)
 	}
+	entry.UpdatedAt = now
+	return nil
+}
+
+func (t *loanTx) RecordPayment(ctx context.Context, payment *loan.Payment) error {
+	sql := `
+        INSERT INTO payments (loan_id, schedule_id, fee_id, amount, channel, reference, collector_id, paid_at, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+        RETURNING id, created_at`
+
+	err := t.tx.QueryRow(ctx, sql, payment.LoanID, payment.ScheduleID, payment.FeeID, payment.Amount, payment.Channel,
+		payment.Reference, payment.CollectorID, payment.PaidAt, t.r.clock.Now()).Scan(&payment.ID, &payment.CreatedAt)
+	if err != nil {
+		return translateDBError(err, t.r.logger.With("loan_id", payment.LoanID, "schedule_id", payment.ScheduleID, "fee_id", payment.FeeID))
+	}
 	return nil
 }
 
-func (r *LoanRepository) UpdateLoanStatusInTx(ctx context.Context, tx pgx.Tx, loanID int64, status loan.LoanStatus) error {
-	sql := `UPDATE loans SET status = $1, updated_at = NOW() WHERE id = $2`
-	cmdTag, err := tx.Exec(ctx, sql, status, loanID)
+func (t *loanTx) UpdateLoanStatus(ctx context.Context, loanID int64, status loan.LoanStatus) error {
+	// The nightly job only visits active loans, so a loan is cleared of its
+	// days past due when it is paid off.
+	sql := `UPDATE loans SET status = $1, days_past_due = CASE WHEN $1 = 'PAID_OFF' THEN 0 ELSE days_past_due END, updated_at = $2 WHERE id = $3`
+	cmdTag, err := t.tx.Exec(ctx, sql, status, t.r.clock.Now(), loanID)
 	if err != nil {
-		r.logger.ErrorContext(ctx, "Failed to update loan status", "loan_id", loanID, "status", status, "error", err)
+		t.r.logger.ErrorContext(ctx, "Failed to update loan status", "loan_id", loanID, "status", status, "error", err)
 		return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
 	}
 	if cmdTag.RowsAffected() != 1 {
-		r.logger.ErrorContext(ctx, "Loan status update affected zero rows", "loan_id", loanID, "status", status)
+		t.r.logger.ErrorContext(ctx, "Loan status update affected zero rows", "loan_id", loanID, "status", status)
 		return fmt.Errorf("%w: loan status update affected zero rows", apperrors.ErrDatabase)
 	}
-	r.logger.InfoContext(ctx, "Loan status updated in DB", "loan_id", loanID, "new_status", status)
+	t.r.logger.InfoContext(ctx, "Loan status updated in DB", "loan_id", loanID, "new_status", status)
 	return nil
 }
 
-func (r *LoanRepository) CheckIfAllPaymentsMadeInTx(ctx context.Context, tx pgx.Tx, loanID int64) (bool, error) {
+func (t *loanTx) CheckIfAllPaymentsMade(ctx context.Context, loanID int64) (bool, error) {
 	var count int
-	query := `SELECT COUNT(*) FROM loan_schedule WHERE loan_id = $1 AND status != 'PAID'`
-	err := tx.QueryRow(ctx, query, loanID).Scan(&count)
+	query := `
+        SELECT (SELECT COUNT(*) FROM loan_schedule WHERE loan_id = $1 AND status != 'PAID')
+             + (SELECT COUNT(*) FROM fees f` + openFeesWhere + `)`
+	err := t.tx.QueryRow(ctx, query, loanID).Scan(&count)
 	if err != nil {
-		r.logger.ErrorContext(ctx, "Failed to count non-paid schedule entries", "loan_id", loanID, "error", err)
+		t.r.logger.ErrorContext(ctx, "Failed to count non-paid schedule entries and fees", "loan_id", loanID, "error", err)
 		return false, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
 	}
 	return count == 0, nil
 }
 
-func (r *LoanRepository) GetTotalOutstandingAmount(ctx context.Context, loanID int64) (float64, error) {
-	var totalOutstanding float64
+// ListBalanceItems returns the direct-debit collections that were received
+// but could not be posted to the schedule, as suspense, followed by the fees
+// that are neither paid nor waived.
+func (r *LoanRepository) ListBalanceItems(ctx context.Context, loanID int64) ([]loan.BalanceItem, error) {
+	query := `
+        SELECT amount, end_to_end_id
+        FROM direct_debit_instructions
+        WHERE loan_id = $1 AND status = 'UNAPPLIED'
+        ORDER BY id`
+	start := time.Now()
+
+	rows, err := r.db.Query(ctx, query, loanID)
+	if err != nil {
+		monitoring.RecordDBQuery("ListBalanceItems", "error", time.Since(start))
+		r.logger.ErrorContext(ctx, "Failed to list balance items", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	items := make([]loan.BalanceItem, 0)
+	for rows.Next() {
+		var endToEndID string
+		item := loan.BalanceItem{Kind: loan.BalanceSuspense}
+		if err := rows.Scan(&item.Amount, &endToEndID); err != nil {
+			monitoring.RecordDBQuery("ListBalanceItems", "error", time.Since(start))
+			return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+		}
+		item.Description = "direct debit " + endToEndID + " collected but not posted"
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		monitoring.RecordDBQuery("ListBalanceItems", "error", time.Since(start))
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	rows.Close()
+
+	fees, err := r.queryFees(ctx, listOpenFeesQuery, loanID)
+	if err != nil {
+		monitoring.RecordDBQuery("ListBalanceItems", "error", time.Since(start))
+		return nil, err
+	}
+	for i := range fees {
+		items = append(items, fees[i].BalanceItem())
+		if fees[i].Tax != nil {
+			items = append(items, fees[i].TaxBalanceItem())
+		}
+	}
+
+	monitoring.RecordDBQuery("ListBalanceItems", "success", time.Since(start))
+	return items, nil
+}
+
+// GetLastModified returns the latest updated_at of the loan and its schedule
+// rows, or the last time a hold was placed or released on it. Payments and
+// the delinquency job only touch the schedule, so the loan row alone would
+// miss them. GREATEST skips the NULLs of a loan without schedule or holds.
+func (r *LoanRepository) GetLastModified(ctx context.Context, loanID int64) (time.Time, error) {
+	query, args := sqlbuilder.ScopeToTenant(ctx, getLastModifiedQuery, "l.tenant_id", loanID)
+	status := "success"
+	startTime := time.Now()
+
+	var lastModified time.Time
+	err := r.db.QueryRow(ctx, query, args...).Scan(&lastModified)
+	if err != nil {
+		status = "error"
+	}
+	monitoring.RecordDBQuery("GetLastModified", status, time.Since(startTime))
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.logger.WarnContext(ctx, "Loan not found", "loan_id", loanID)
+			return time.Time{}, apperrors.ErrNotFound
+		}
+		r.logger.ErrorContext(ctx, "Failed to get loan last modified time", "loan_id", loanID, "error", err)
+		return time.Time{}, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	return lastModified, nil
+}
 
+const getLastModifiedQuery = `
+        SELECT GREATEST(l.updated_at,
+               (SELECT MAX(s.updated_at) FROM loan_schedule s WHERE s.loan_id = l.id),
+               (SELECT MAX(COALESCE(h.released_at, h.placed_at)) FROM loan_holds h WHERE h.loan_id = l.id))
+        FROM loans l
+        WHERE l.id = $1`
+
+// SumPaymentsByChannel reads the payments ledger, which holds every
+// installment payment including those backfilled from the schedule by
+// migration 012, and the prepayments, which are kept apart from it. Both are
+// read with their archive tables, so archival leaves past periods unchanged.
+func (r *LoanRepository) SumPaymentsByChannel(ctx context.Context, from, to time.Time) ([]loan.ChannelCollections, error) {
 	query := `
-        SELECT COALESCE(SUM(due_amount - paid_amount), 0.00)
-        FROM loan_schedule
-        WHERE loan_id = $1 AND status != 'PAID'`
+        SELECT channel, COUNT(*), COALESCE(SUM(amount), 0)
+        FROM (
+            SELECT channel, amount FROM payments WHERE paid_at >= $1 AND paid_at < $2
+            UNION ALL
+            SELECT channel, amount FROM payments_archive WHERE paid_at >= $1 AND paid_at < $2
+            UNION ALL
+            SELECT channel, amount FROM prepayments WHERE paid_at >= $1 AND paid_at < $2
+            UNION ALL
+            SELECT channel, amount FROM prepayments_archive WHERE paid_at >= $1 AND paid_at < $2
+        ) received
+        GROUP BY channel
+        ORDER BY channel`
+	start := time.Now()
+
+	rows, err := r.db.Query(ctx, query, from, to)
+	if err != nil {
+		monitoring.RecordDBQuery("SumPaymentsByChannel", "error", time.Since(start))
+		r.logger.ErrorContext(ctx, "Failed to sum payments by channel", "from", from, "to", to, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	totals := make([]loan.ChannelCollections, 0)
+	for rows.Next() {
+		var c loan.ChannelCollections
+		if err := rows.Scan(&c.Channel, &c.Payments, &c.Amount); err != nil {
+			monitoring.RecordDBQuery("SumPaymentsByChannel", "error", time.Since(start))
+			return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+		}
+		totals = append(totals, c)
+	}
+	if err := rows.Err(); err != nil {
+		monitoring.RecordDBQuery("SumPaymentsByChannel", "error", time.Since(start))
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+
+	monitoring.RecordDBQuery("SumPaymentsByChannel", "success", time.Since(start))
+	return totals, nil
+}
 
-	err := r.db.QueryRow(ctx, query, loanID).Scan(&totalOutstanding)
+func (r *LoanRepository) ListPayments(ctx context.Context, loanID int64, limit int) ([]loan.Payment, error) {
+	query := `
+        SELECT id, loan_id, schedule_id, fee_id, amount, channel, reference, collector_id, paid_at, created_at
+        FROM payments WHERE loan_id = $1
+        ORDER BY paid_at DESC, id DESC
+        LIMIT $2`
+	start := time.Now()
+
+	rows, err := r.db.Query(ctx, query, loanID, limit)
 	if err != nil {
+		monitoring.RecordDBQuery("ListPayments", "error", time.Since(start))
+		r.logger.ErrorContext(ctx, "Failed to list payments", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
 
-		if !errors.Is(err, pgx.ErrNoRows) {
-			r.logger.ErrorContext(ctx, "Failed to calculate total outstanding amount", "loan_id", loanID, "error", err)
-			return 0, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	payments := make([]loan.Payment, 0)
+	for rows.Next() {
+		var p loan.Payment
+		if err := rows.Scan(&p.ID, &p.LoanID, &p.ScheduleID, &p.FeeID, &p.Amount, &p.Channel, &p.Reference, &p.CollectorID, &p.PaidAt, &p.CreatedAt); err != nil {
+			monitoring.RecordDBQuery("ListPayments", "error", time.Since(start))
+			return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
 		}
+		payments = append(payments, p)
 	}
+	if err := rows.Err(); err != nil {
+		monitoring.RecordDBQuery("ListPayments", "error", time.Since(start))
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+
+	monitoring.RecordDBQuery("ListPayments", "success", time.Since(start))
+	return payments, nil
+}
+
+// searchPaymentsQuery reads payments and prepayments as one ledger. The
+// conditions SearchLoans adds on r are pushed down into both arms, where
+// the reference and paid_at indexes serve them.
+const searchPaymentsQuery = `
+        SELECT l.id, l.public_id, l.status, l.days_past_due, c.id, c.name,
+               r.id, r.amount, r.channel, r.reference, r.paid_at, r.prepayment
+        FROM (
+            SELECT id, loan_id, amount, channel, reference, paid_at, FALSE AS prepayment FROM payments
+            UNION ALL
+            SELECT id, loan_id, amount, channel, reference, paid_at, TRUE AS prepayment FROM prepayments
+        ) r
+        JOIN loans l ON l.id = r.loan_id
+        LEFT JOIN customers c ON c.loan_id = l.id`
+
+const searchCustomersQuery = `
+        SELECT l.id, l.public_id, l.status, l.days_past_due, c.id, c.name
+        FROM customers c
+        JOIN loans l ON l.id = c.loan_id`
+
+func (r *LoanRepository) SearchLoans(ctx context.Context, q loan.LoanSearch) ([]loan.SearchMatch, error) {
+	var b *sqlbuilder.SelectBuilder
+	if q.SearchesPayments() {
+		low, high := q.AmountRange()
+		b = sqlbuilder.Select(searchPaymentsQuery).
+			WhereIf(q.Reference != "", "r.reference = ?", q.Reference).
+			WhereIf(q.Amount != nil, "r.amount BETWEEN ? AND ?", low, high).
+			WhereIf(!q.From.IsZero(), "r.paid_at >= ? AND r.paid_at < ?", q.From, q.To).
+			OrderBy("", nil, "r.paid_at DESC, r.id DESC")
+	} else {
+		b = sqlbuilder.Select(searchCustomersQuery).OrderBy("", nil, "c.name, l.id")
+	}
+	query, args, err := b.
+		WhereIf(q.CustomerName != "", `lower(c.name) LIKE lower(?) ESCAPE '\'`, sqlbuilder.Contains(q.CustomerName)).
+		WhereTenant(ctx, "l.tenant_id").
+		Page(q.Limit, 0).
+		Build()
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
 
-	if totalOutstanding < 0 {
-		r.logger.WarnContext(ctx, "Calculated outstanding amount is negative, returning 0", "loan_id", loanID, "calculated_value", totalOutstanding)
-		return 0, nil
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		monitoring.RecordDBQuery("SearchLoans", "error", time.Since(start))
+		r.logger.ErrorContext(ctx, "Failed to search loans", "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	matches := make([]loan.SearchMatch, 0)
+	for rows.Next() {
+		var m loan.SearchMatch
+		dest := []any{&m.LoanID, &m.LoanPublicID, &m.Status, &m.DaysPastDue, &m.CustomerID, &m.CustomerName}
+		if q.SearchesPayments() {
+			m.Payment = &loan.PaymentMatch{}
+			dest = append(dest, &m.Payment.ID, &m.Payment.Amount, &m.Payment.Channel, &m.Payment.Reference, &m.Payment.PaidAt, &m.Payment.Prepayment)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			monitoring.RecordDBQuery("SearchLoans", "error", time.Since(start))
+			return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+		}
+		matches = append(matches, m)
+	}
+	if err := rows.Err(); err != nil {
+		monitoring.RecordDBQuery("SearchLoans", "error", time.Since(start))
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
 	}
 
-	return totalOutstanding, nil
+	monitoring.RecordDBQuery("SearchLoans", "success", time.Since(start))
+	return matches, nil
 }
 
 func translateDBError(err error, contextLogger *slog.Logger) error {
@@ -417,34 +851,102 @@ func translateDBError(err error, contextLogger *slog.Logger) error {
 	return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
 }
 
-func (r *LoanRepository) GetAllActiveLoanIDs(ctx context.Context) ([]int64, error) {
-	logCtx := r.logger.With(slog.String("operation", "GetAllActiveLoanIDs"))
-	logCtx.DebugContext(ctx, "Attempting to get all active loan IDs")
+func (r *LoanRepository) GetActiveLoanTenants(ctx context.Context) (map[int64]string, error) {
+	logCtx := r.logger.With(slog.String("operation", "GetActiveLoanTenants"))
+	logCtx.DebugContext(ctx, "Attempting to get all active loans")
 
-	query := `SELECT id FROM loans WHERE status = $1 ORDER BY id`
+	query, args := sqlbuilder.ScopeToTenant(ctx, `SELECT id, tenant_id FROM loans WHERE status = $1`, "tenant_id", loan.StatusActive)
 
-	rows, err := r.db.Query(ctx, query, loan.StatusActive)
+	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
-		logCtx.ErrorContext(ctx, "Failed to query active loan IDs", slog.Any("error", err))
+		logCtx.ErrorContext(ctx, "Failed to query active loans", slog.Any("error", err))
 		return nil, fmt.Errorf("%w: failed to query active loans: %w", apperrors.ErrDatabase, err)
 	}
 	defer rows.Close()
 
-	loanIDs := make([]int64, 0)
+	tenants := make(map[int64]string)
 	for rows.Next() {
 		var id int64
-		if err := rows.Scan(&id); err != nil {
-			logCtx.ErrorContext(ctx, "Failed to scan active loan ID row", slog.Any("error", err))
-			return nil, fmt.Errorf("%w: failed scanning active loan ID: %w", apperrors.ErrDatabase, err)
+		var tenantID string
+		if err := rows.Scan(&id, &tenantID); err != nil {
+			logCtx.ErrorContext(ctx, "Failed to scan active loan row", slog.Any("error", err))
+			return nil, fmt.Errorf("%w: failed scanning active loan: %w", apperrors.ErrDatabase, err)
 		}
-		loanIDs = append(loanIDs, id)
+		tenants[id] = tenantID
 	}
 
 	if err = rows.Err(); err != nil {
-		logCtx.ErrorContext(ctx, "Error iterating active loan ID rows", slog.Any("error", err))
-		return nil, fmt.Errorf("%w: error iterating active loan IDs: %w", apperrors.ErrDatabase, err)
+		logCtx.ErrorContext(ctx, "Error iterating active loan rows", slog.Any("error", err))
+		return nil, fmt.Errorf("%w: error iterating active loans: %w", apperrors.ErrDatabase, err)
+	}
+
+	logCtx.DebugContext(ctx, "Finished getting active loans", slog.Int("count", len(tenants)))
+	return tenants, nil
+}
+
+func (r *LoanRepository) UpdateDaysPastDue(ctx context.Context, loanID int64, daysPastDue int) error {
+	query := `UPDATE loans SET days_past_due = $1, updated_at = $2 WHERE id = $3 AND days_past_due <> $1`
+	start := time.Now()
+	if _, err := r.db.Exec(ctx, query, daysPastDue, r.clock.Now(), loanID); err != nil {
+		monitoring.RecordDBQuery("UpdateDaysPastDue", "error", time.Since(start))
+		r.logger.ErrorContext(ctx, "Failed to update days past due", "loan_id", loanID, "error", err)
+		return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	monitoring.RecordDBQuery("UpdateDaysPastDue", "success", time.Since(start))
+	return nil
+}
+
+// StreamLoans reads loans with a keyset on the primary key and hands each row
+// to fn before scanning the next one, so exports of the whole book run in
+// constant memory.
+func (r *LoanRepository) StreamLoans(ctx context.Context, filter loan.LoanFilter, fn func(*loan.Loan) error) error {
+	query, args, err := sqlbuilder.Select(`
+        SELECT id, public_id, principal_amount, interest_rate, term_weeks, weekly_payment_amount, total_loan_amount, start_date, status, days_past_due, external_ref, created_at, updated_at,
+               next_due_date, next_due_amount, installments_paid, installments_remaining, schedule_type, interest_only_weeks, balloon_amount
+        FROM loans`).
+		Where("id > ?", filter.AfterID).
+		WhereIf(filter.MinDaysPastDue > 0, "days_past_due >= ?", filter.MinDaysPastDue).
+		WhereIf(filter.Status != "", "status = ?", filter.Status).
+		WhereTenant(ctx, "tenant_id").
+		OrderBy("", nil, "id").
+		Build()
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		monitoring.RecordDBQuery("StreamLoans", "error", time.Since(start))
+		r.logger.ErrorContext(ctx, "Failed to query loans for streaming", "after_id", filter.AfterID, "error", err)
+		return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var l loan.Loan
+		if err := rows.Scan(
+			&l.ID, &l.PublicID, &l.PrincipalAmount, &l.InterestRate, &l.TermWeeks,
+			&l.WeeklyPaymentAmount, &l.TotalLoanAmount, &l.StartDate,
+			&l.Status, &l.DaysPastDue, &l.ExternalRef, &l.CreatedAt, &l.UpdatedAt,
+			&l.NextDueDate, &l.NextDueAmount, &l.InstallmentsPaid, &l.InstallmentsRemaining,
+			&l.Structure.Type, &l.Structure.InterestOnlyWeeks, &l.Structure.BalloonAmount,
+		); err != nil {
+			monitoring.RecordDBQuery("StreamLoans", "error", time.Since(start))
+			r.logger.ErrorContext(ctx, "Failed to scan streamed loan row", "after_id", filter.AfterID, "error", err)
+			return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+		}
+		if err := fn(&l); err != nil {
+			monitoring.RecordDBQuery("StreamLoans", "aborted", time.Since(start))
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		monitoring.RecordDBQuery("StreamLoans", "error", time.Since(start))
+		r.logger.ErrorContext(ctx, "Error iterating streamed loan rows", "after_id", filter.AfterID, "error", err)
+		return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
 	}
 
-	logCtx.DebugContext(ctx, "Finished getting active loan IDs", slog.Int("count", len(loanIDs)))
-	return loanIDs, nil
+	monitoring.RecordDBQuery("StreamLoans", "success", time.Since(start))
+	return nil
 }
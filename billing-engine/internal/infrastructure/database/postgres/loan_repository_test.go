@@ -3,6 +3,8 @@ package postgres
 import (
 	"billing-engine/internal/domain/loan"
 	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"billing-engine/internal/pkg/scope"
 	"context"
 	"errors"
 	"io"
@@ -11,6 +13,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgtype"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -56,119 +59,96 @@ func (m *MockDB) BeginTx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error
 
 var logger = slog.New(slog.NewTextHandler(io.Discard, nil))
 
+// testClock fixes the timestamps the repositories write so that the mocks can
+// match them as arguments.
+var testClock = clock.NewFake(time.Date(2025, 1, 6, 9, 30, 0, 0, time.UTC))
+
 const pgxmockExpectationsNotMetMsg = "pgxmock expectations not met"
 
 func setupLoanRepo(t *testing.T) (context.Context, *LoanRepository, pgxmock.PgxPoolIface) {
 	t.Helper()
 	mockPool, err := pgxmock.NewPool()
 	require.NoError(t, err, "Failed to create mock pool")
-	repo := NewLoanRepository(mockPool, logger)
+	repo := NewLoanRepository(mockPool, testClock, logger)
 	ctx := context.Background()
 
 	return ctx, repo, mockPool
 }
 
-func TestLoanRepositoryBeginTxSuccess(t *testing.T) {
-	ctx, repo, mockPool := setupLoanRepo(t)
-	defer mockPool.Close()
-
-	mockPool.ExpectBegin()
-
-	tx, err := repo.BeginTx(ctx)
-
-	assert.NoError(t, err)
-	assert.NotNil(t, tx)
-	assert.NoError(t, mockPool.ExpectationsWereMet())
-}
-
-func TestLoanRepositoryBeginTxError(t *testing.T) {
-	ctx, repo, mockPool := setupLoanRepo(t)
-	defer mockPool.Close()
-
-	dbErr := errors.New("db begin failed")
-	mockPool.ExpectBegin().WillReturnError(dbErr)
-
-	tx, err := repo.BeginTx(ctx)
-
-	assert.Error(t, err)
-	assert.Nil(t, tx)
-	assert.ErrorIs(t, err, apperrors.ErrDatabase)
-	assert.ErrorContains(t, err, dbErr.Error())
-	assert.NoError(t, mockPool.ExpectationsWereMet())
+// inTx wraps mockPool as the transaction a WithinTransaction callback gets.
+func inTx(repo *LoanRepository, mockPool pgxmock.PgxPoolIface) *loanTx {
+	return &loanTx{r: repo, tx: mockPool}
 }
 
-func TestLoanRepositoryCommitTxSuccess(t *testing.T) {
-	ctx, repo, mockPool := setupLoanRepo(t)
-	defer mockPool.Close()
-	mockPool.ExpectBegin()
-	mockTx, err := mockPool.Begin(ctx)
-	require.NoError(t, err)
-
-	mockTx.(pgxmock.PgxCommonIface).ExpectCommit()
-
-	err = repo.CommitTx(ctx, mockTx)
-
-	assert.NoError(t, err)
-
-}
-
-func TestLoanRepositoryCommitTxError(t *testing.T) {
-	ctx, repo, mockPool := setupLoanRepo(t)
-	defer mockPool.Close()
-	mockPool.ExpectBegin()
-	mockTx, err := mockPool.Begin(ctx)
-	require.NoError(t, err)
-
-	mockTx.(pgxmock.PgxCommonIface).ExpectCommit().WillReturnError(pgx.ErrTxClosed)
-	err = repo.CommitTx(ctx, mockTx)
-
-	assert.Error(t, err)
-	assert.ErrorIs(t, err, apperrors.ErrDatabase)
-}
-
-func TestLoanRepositoryRollbackTxSuccess(t *testing.T) {
-	ctx, repo, mockPool := setupLoanRepo(t)
-	defer mockPool.Close()
-	mockPool.ExpectBegin()
-	mockTx, err := mockPool.Begin(ctx)
-	require.NoError(t, err)
-
-	mockTx.(pgxmock.PgxCommonIface).ExpectRollback()
-
-	err = repo.RollbackTx(ctx, mockTx)
-
-	assert.NoError(t, err)
-}
-
-func TestLoanRepositoryRollbackTxError(t *testing.T) {
-	ctx, repo, mockPool := setupLoanRepo(t)
-	defer mockPool.Close()
-	mockPool.ExpectBegin()
-	mockTx, err := mockPool.Begin(ctx)
-	require.NoError(t, err)
-
-	dbErr := errors.New("db rollback failed")
-	mockTx.(pgxmock.PgxCommonIface).ExpectRollback().WillReturnError(dbErr)
-
-	err = repo.RollbackTx(ctx, mockTx)
-
-	assert.Error(t, err)
-	assert.ErrorIs(t, err, apperrors.ErrDatabase)
-	assert.ErrorContains(t, err, dbErr.Error())
-}
-
-func TestRollbackTxErrorTxClosed(t *testing.T) {
-	ctx, repo, mockPool := setupLoanRepo(t)
-	defer mockPool.Close()
-	mockPool.ExpectBegin()
-	mockTx, err := mockPool.Begin(ctx)
-	require.NoError(t, err)
-
-	mockTx.(pgxmock.PgxCommonIface).ExpectRollback().WillReturnError(pgx.ErrTxClosed)
-
-	err = repo.RollbackTx(ctx, mockTx)
-
-	assert.NoError(t, err)
+func TestLoanRepositoryWithinTransaction(t *testing.T) {
+	t.Run("commits when fn succeeds", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+		mockPool.ExpectBegin()
+		mockPool.ExpectQuery(regexp.QuoteMeta(lockLoanForPaymentQuery)).WithArgs(int64(1)).
+			WillReturnRows(pgxmock.NewRows([]string{"locked"}).AddRow(true))
+		mockPool.ExpectCommit()
+
+		err := repo.WithinTransaction(ctx, func(tx loan.TxRepository) error {
+			return tx.LockLoanForPayment(ctx, 1)
+		})
+
+		assert.NoError(t, err)
+		assert.NoError(t, mockPool.ExpectationsWereMet())
+	})
+
+	t.Run("rolls back and returns fn's error", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+		mockPool.ExpectBegin()
+		mockPool.ExpectRollback()
+
+		err := repo.WithinTransaction(ctx, func(loan.TxRepository) error { return apperrors.ErrLoanOnHold })
+
+		assert.ErrorIs(t, err, apperrors.ErrLoanOnHold)
+		assert.NoError(t, mockPool.ExpectationsWereMet())
+	})
+
+	t.Run("rolls back when fn panics", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+		mockPool.ExpectBegin()
+		mockPool.ExpectRollback()
+
+		assert.Panics(t, func() {
+			_ = repo.WithinTransaction(ctx, func(loan.TxRepository) error { panic("boom") })
+		})
+		assert.NoError(t, mockPool.ExpectationsWereMet())
+	})
+
+	t.Run("begin error", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+		dbErr := errors.New("db begin failed")
+		mockPool.ExpectBegin().WillReturnError(dbErr)
+
+		err := repo.WithinTransaction(ctx, func(loan.TxRepository) error {
+			t.Fatal("fn must not run without a transaction")
+			return nil
+		})
+
+		assert.ErrorIs(t, err, apperrors.ErrDatabase)
+		assert.ErrorContains(t, err, dbErr.Error())
+		assert.NoError(t, mockPool.ExpectationsWereMet())
+	})
+
+	t.Run("commit error", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+		mockPool.ExpectBegin()
+		mockPool.ExpectCommit().WillReturnError(errors.New("serialization failure"))
+		mockPool.ExpectRollback()
+
+		err := repo.WithinTransaction(ctx, func(loan.TxRepository) error { return nil })
+
+		assert.ErrorIs(t, err, apperrors.ErrDatabase)
+		assert.NoError(t, mockPool.ExpectationsWereMet())
+	})
 }
 
 func TestCreateLoanWithSchedule(t *testing.T) {
@@ -184,6 +164,7 @@ func TestCreateLoanWithSchedule(t *testing.T) {
 	}
 
 	newLoan := &loan.Loan{
+		PublicID:            uuid.New(),
 		PrincipalAmount:     1000.0,
 		InterestRate:        5.0,
 		TermWeeks:           2,
@@ -197,41 +178,42 @@ func TestCreateLoanWithSchedule(t *testing.T) {
 	mockPool.ExpectBegin()
 
 	loanSQL := `
-        INSERT INTO loans (principal_amount, interest_rate, term_weeks, weekly_payment_amount, total_loan_amount, start_date, status, created_at, updated_at)
-        VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
-        RETURNING id, principal_amount, interest_rate, term_weeks, weekly_payment_amount, total_loan_amount, start_date, status, created_at, updated_at`
+        INSERT INTO loans (public_id, principal_amount, interest_rate, term_weeks, weekly_payment_amount, total_loan_amount, start_date, status, external_ref, created_at, updated_at,
+                           schedule_type, interest_only_weeks, balloon_amount, tenant_id)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $10, $11, $12, $13, $14)
+        RETURNING id, public_id, principal_amount, interest_rate, term_weeks, weekly_payment_amount, total_loan_amount, start_date, status, days_past_due, external_ref, created_at, updated_at,
+                  schedule_type, interest_only_weeks, balloon_amount`
 
 	loanRows := pgxmock.NewRows([]string{
-		"id", "principal_amount", "interest_rate", "term_weeks", "weekly_payment_amount",
-		"total_loan_amount", "start_date", "status", "created_at", "updated_at",
+		"id", "public_id", "principal_amount", "interest_rate", "term_weeks", "weekly_payment_amount",
+		"total_loan_amount", "start_date", "status", "days_past_due", "external_ref", "created_at", "updated_at",
+		"schedule_type", "interest_only_weeks", "balloon_amount",
 	}).AddRow(
-		testLoanID, newLoan.PrincipalAmount, newLoan.InterestRate, newLoan.TermWeeks,
+		testLoanID, newLoan.PublicID, newLoan.PrincipalAmount, newLoan.InterestRate, newLoan.TermWeeks,
 		newLoan.WeeklyPaymentAmount, newLoan.TotalLoanAmount, newLoan.StartDate,
-		newLoan.Status, now, now,
+		newLoan.Status, 0, newLoan.ExternalRef, now, now,
+		loan.ScheduleStandard, 0, 0.0,
 	)
 	mockPool.ExpectQuery(regexp.QuoteMeta(loanSQL)).
-		WithArgs(newLoan.PrincipalAmount, newLoan.InterestRate, newLoan.TermWeeks, newLoan.WeeklyPaymentAmount, newLoan.TotalLoanAmount, newLoan.StartDate, newLoan.Status).
+		WithArgs(newLoan.PublicID, newLoan.PrincipalAmount, newLoan.InterestRate, newLoan.TermWeeks, newLoan.WeeklyPaymentAmount, newLoan.TotalLoanAmount, newLoan.StartDate, newLoan.Status, newLoan.ExternalRef, testClock.Now(),
+			loan.ScheduleStandard, 0, 0.0, scope.DefaultTenant).
 		WillReturnRows(loanRows)
 
 	scheduleSQL := `
             INSERT INTO loan_schedule (loan_id, week_number, due_date, due_amount, status, created_at, updated_at)
-            VALUES ($1, $2, $3, $4, $5, NOW(), NOW())`
+            VALUES ($1, $2, $3, $4, $5, $6, $6)`
 	expectBatch := mockPool.ExpectBatch()
 	batch := &pgx.Batch{}
 
 	for _, entry := range schedule {
 		expectBatch.ExpectExec(regexp.QuoteMeta(scheduleSQL)).
-			WithArgs(testLoanID, entry.WeekNumber, entry.DueDate, entry.DueAmount, entry.Status).
+			WithArgs(testLoanID, entry.WeekNumber, entry.DueDate, entry.DueAmount, entry.Status, testClock.Now()).
 			WillReturnResult(pgxmock.NewResult("INSERT", 1))
-		batch.Queue(regexp.QuoteMeta(scheduleSQL), testLoanID, entry.WeekNumber, entry.DueDate, entry.DueAmount, entry.Status)
+		batch.Queue(regexp.QuoteMeta(scheduleSQL), testLoanID, entry.WeekNumber, entry.DueDate, entry.DueAmount, entry.Status, testClock.Now())
 	}
 
 	mockPool.SendBatch(ctx, batch)
-	updateCustomerSQL := `
-        UPDATE customers
-        SET loan_id = $1, updated_at = NOW()
-        WHERE id = $2 AND loan_id IS NULL`
-	mockPool.ExpectExec(regexp.QuoteMeta(updateCustomerSQL)).WithArgs(testLoanID, int64(1)).
+	mockPool.ExpectExec(regexp.QuoteMeta(linkLoanToCustomerQuery)).WithArgs(testLoanID, testClock.Now(), int64(1), scope.DefaultTenant).
 		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
 	mockPool.ExpectCommit()
 
@@ -243,6 +225,8 @@ func TestCreateLoanWithSchedule(t *testing.T) {
 	require.NotNil(t, createdLoan)
 	assert.Equal(t, testLoanID, createdLoan.ID)
 	assert.Equal(t, len(newLoan.Schedule), len(schedule))
+	assert.Equal(t, schedule[0].DueDate, *createdLoan.NextDueDate, "the summary is taken from the schedule written")
+	assert.Equal(t, len(schedule), createdLoan.InstallmentsRemaining)
 	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
 }
 
@@ -254,6 +238,7 @@ func TestLoanRepositoryCreateLoanSuccessNoSchedule(t *testing.T) {
 	testLoanID := int64(124)
 	customerID := int64(1)
 	newLoan := &loan.Loan{
+		PublicID:            uuid.New(),
 		PrincipalAmount:     2000.0,
 		InterestRate:        4.0,
 		TermWeeks:           5,
@@ -267,27 +252,28 @@ func TestLoanRepositoryCreateLoanSuccessNoSchedule(t *testing.T) {
 	mockPool.ExpectBegin()
 
 	loanSQL := `
-        INSERT INTO loans (principal_amount, interest_rate, term_weeks, weekly_payment_amount, total_loan_amount, start_date, status, created_at, updated_at)
-        VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
-        RETURNING id, principal_amount, interest_rate, term_weeks, weekly_payment_amount, total_loan_amount, start_date, status, created_at, updated_at`
+        INSERT INTO loans (public_id, principal_amount, interest_rate, term_weeks, weekly_payment_amount, total_loan_amount, start_date, status, external_ref, created_at, updated_at,
+                           schedule_type, interest_only_weeks, balloon_amount, tenant_id)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $10, $11, $12, $13, $14)
+        RETURNING id, public_id, principal_amount, interest_rate, term_weeks, weekly_payment_amount, total_loan_amount, start_date, status, days_past_due, external_ref, created_at, updated_at,
+                  schedule_type, interest_only_weeks, balloon_amount`
 
 	loanRows := pgxmock.NewRows([]string{
-		"id", "principal_amount", "interest_rate", "term_weeks", "weekly_payment_amount",
-		"total_loan_amount", "start_date", "status", "created_at", "updated_at",
+		"id", "public_id", "principal_amount", "interest_rate", "term_weeks", "weekly_payment_amount",
+		"total_loan_amount", "start_date", "status", "days_past_due", "external_ref", "created_at", "updated_at",
+		"schedule_type", "interest_only_weeks", "balloon_amount",
 	}).AddRow(
-		testLoanID, newLoan.PrincipalAmount, newLoan.InterestRate, newLoan.TermWeeks,
+		testLoanID, newLoan.PublicID, newLoan.PrincipalAmount, newLoan.InterestRate, newLoan.TermWeeks,
 		newLoan.WeeklyPaymentAmount, newLoan.TotalLoanAmount, newLoan.StartDate,
-		newLoan.Status, now, now,
+		newLoan.Status, 0, newLoan.ExternalRef, now, now,
+		loan.ScheduleStandard, 0, 0.0,
 	)
 	mockPool.ExpectQuery(regexp.QuoteMeta(loanSQL)).
-		WithArgs(newLoan.PrincipalAmount, newLoan.InterestRate, newLoan.TermWeeks, newLoan.WeeklyPaymentAmount, newLoan.TotalLoanAmount, newLoan.StartDate, newLoan.Status).
+		WithArgs(newLoan.PublicID, newLoan.PrincipalAmount, newLoan.InterestRate, newLoan.TermWeeks, newLoan.WeeklyPaymentAmount, newLoan.TotalLoanAmount, newLoan.StartDate, newLoan.Status, newLoan.ExternalRef, testClock.Now(),
+			loan.ScheduleStandard, 0, 0.0, scope.DefaultTenant).
 		WillReturnRows(loanRows)
 
-	updateCustomerSQL := `
-        UPDATE customers
-        SET loan_id = $1, updated_at = NOW()
-        WHERE id = $2 AND loan_id IS NULL`
-	mockPool.ExpectExec(regexp.QuoteMeta(updateCustomerSQL)).WithArgs(testLoanID, int64(1)).
+	mockPool.ExpectExec(regexp.QuoteMeta(linkLoanToCustomerQuery)).WithArgs(testLoanID, testClock.Now(), int64(1), scope.DefaultTenant).
 		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
 	mockPool.ExpectCommit()
 
@@ -299,22 +285,86 @@ func TestLoanRepositoryCreateLoanSuccessNoSchedule(t *testing.T) {
 	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
 }
 
+func TestLoanRepositoryCreateLoanRollsBackWhenCustomerCannotBeLinked(t *testing.T) {
+	now := time.Now()
+	newLoan := &loan.Loan{PublicID: uuid.New(), PrincipalAmount: 2000, TermWeeks: 5, StartDate: now, Status: loan.StatusActive}
+	expectLoanInsert := func(mockPool pgxmock.PgxPoolIface) {
+		mockPool.ExpectBegin()
+		mockPool.ExpectQuery(`INSERT INTO loans`).
+			WithArgs(newLoan.PublicID, newLoan.PrincipalAmount, newLoan.InterestRate, newLoan.TermWeeks, newLoan.WeeklyPaymentAmount, newLoan.TotalLoanAmount, newLoan.StartDate, newLoan.Status, newLoan.ExternalRef, testClock.Now(),
+				loan.ScheduleStandard, 0, 0.0, scope.DefaultTenant).
+			WillReturnRows(pgxmock.NewRows([]string{
+				"id", "public_id", "principal_amount", "interest_rate", "term_weeks", "weekly_payment_amount",
+				"total_loan_amount", "start_date", "status", "days_past_due", "external_ref", "created_at", "updated_at",
+				"schedule_type", "interest_only_weeks", "balloon_amount",
+			}).AddRow(int64(124), newLoan.PublicID, 2000.0, 0.0, 5, 0.0, 0.0, now, loan.StatusActive, 0, nil, now, now, loan.ScheduleStandard, 0, 0.0))
+	}
+
+	t.Run("customer inactive or holding an active loan", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+		expectLoanInsert(mockPool)
+		mockPool.ExpectExec(regexp.QuoteMeta(linkLoanToCustomerQuery)).WithArgs(int64(124), testClock.Now(), int64(1), scope.DefaultTenant).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+		mockPool.ExpectRollback()
+
+		createdLoan, err := repo.CreateLoan(ctx, 1, newLoan, nil)
+
+		assert.Nil(t, createdLoan)
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), "the loan is rolled back, not committed")
+	})
+
+	t.Run("link fails", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+		expectLoanInsert(mockPool)
+		mockPool.ExpectExec(regexp.QuoteMeta(linkLoanToCustomerQuery)).WithArgs(int64(124), testClock.Now(), int64(1), scope.DefaultTenant).
+			WillReturnError(errors.New("connection reset"))
+		mockPool.ExpectRollback()
+
+		createdLoan, err := repo.CreateLoan(ctx, 1, newLoan, nil)
+
+		assert.Nil(t, createdLoan)
+		assert.ErrorIs(t, err, apperrors.ErrDatabase)
+		assert.NoError(t, mockPool.ExpectationsWereMet())
+	})
+
+	t.Run("commit fails", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+		expectLoanInsert(mockPool)
+		mockPool.ExpectExec(regexp.QuoteMeta(linkLoanToCustomerQuery)).WithArgs(int64(124), testClock.Now(), int64(1), scope.DefaultTenant).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mockPool.ExpectCommit().WillReturnError(errors.New("serialization failure"))
+		mockPool.ExpectRollback()
+
+		createdLoan, err := repo.CreateLoan(ctx, 1, newLoan, nil)
+
+		assert.Nil(t, createdLoan)
+		assert.ErrorIs(t, err, apperrors.ErrDatabase)
+	})
+}
+
 func TestCreateLoanErrorLoanInsertFails(t *testing.T) {
 	ctx, repo, mockPool := setupLoanRepo(t)
 	defer mockPool.Close()
 
-	newLoan := &loan.Loan{ /* ... minimal setup ... */ }
+	newLoan := &loan.Loan{PublicID: uuid.New() /* ... minimal setup ... */}
 	var schedule []loan.ScheduleEntry
 
 	dbErr := errors.New("failed to insert loan")
 
 	mockPool.ExpectBegin()
 	loanSQL := `
-        INSERT INTO loans (principal_amount, interest_rate, term_weeks, weekly_payment_amount, total_loan_amount, start_date, status, created_at, updated_at)
-        VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
-        RETURNING id, principal_amount, interest_rate, term_weeks, weekly_payment_amount, total_loan_amount, start_date, status, created_at, updated_at`
+        INSERT INTO loans (public_id, principal_amount, interest_rate, term_weeks, weekly_payment_amount, total_loan_amount, start_date, status, external_ref, created_at, updated_at,
+                           schedule_type, interest_only_weeks, balloon_amount, tenant_id)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $10, $11, $12, $13, $14)
+        RETURNING id, public_id, principal_amount, interest_rate, term_weeks, weekly_payment_amount, total_loan_amount, start_date, status, days_past_due, external_ref, created_at, updated_at,
+                  schedule_type, interest_only_weeks, balloon_amount`
 	mockPool.ExpectQuery(regexp.QuoteMeta(loanSQL)).
-		WithArgs(newLoan.PrincipalAmount, newLoan.InterestRate, newLoan.TermWeeks, newLoan.WeeklyPaymentAmount, newLoan.TotalLoanAmount, newLoan.StartDate, newLoan.Status).
+		WithArgs(newLoan.PublicID, newLoan.PrincipalAmount, newLoan.InterestRate, newLoan.TermWeeks, newLoan.WeeklyPaymentAmount, newLoan.TotalLoanAmount, newLoan.StartDate, newLoan.Status, newLoan.ExternalRef, testClock.Now(),
+			loan.ScheduleStandard, 0, 0.0, scope.DefaultTenant).
 		WillReturnError(dbErr)
 
 	mockPool.ExpectRollback()
@@ -338,7 +388,7 @@ func TestLoanRepositoryGetLoanByIDSuccess(t *testing.T) {
 	}
 	defer mockDB.Close()
 
-	repo := NewLoanRepository(mockDB, logger)
+	repo := NewLoanRepository(mockDB, testClock, logger)
 
 	ctx := context.Background()
 	loanID := int64(1)
@@ -357,16 +407,20 @@ func TestLoanRepositoryGetLoanByIDSuccess(t *testing.T) {
 	}
 
 	query := `
-        SELECT id, principal_amount, interest_rate, term_weeks, weekly_payment_amount, total_loan_amount, start_date, status, created_at, updated_at
+        SELECT id, public_id, principal_amount, interest_rate, term_weeks, weekly_payment_amount, total_loan_amount, start_date, status, days_past_due, external_ref, created_at, updated_at,
+               next_due_date, next_due_amount, installments_paid, installments_remaining, schedule_type, interest_only_weeks, balloon_amount
         FROM loans
         WHERE id = $1`
 	rows := pgxmock.NewRows([]string{
-		"id", "principal_amount", "interest_rate", "term_weeks", "weekly_payment_amount",
-		"total_loan_amount", "start_date", "status", "created_at", "updated_at",
+		"id", "public_id", "principal_amount", "interest_rate", "term_weeks", "weekly_payment_amount",
+		"total_loan_amount", "start_date", "status", "days_past_due", "external_ref", "created_at", "updated_at",
+		"next_due_date", "next_due_amount", "installments_paid", "installments_remaining", "schedule_type", "interest_only_weeks", "balloon_amount",
 	}).AddRow(
-		expectedLoan.ID, expectedLoan.PrincipalAmount, expectedLoan.InterestRate, expectedLoan.TermWeeks,
+		expectedLoan.ID, expectedLoan.PublicID, expectedLoan.PrincipalAmount, expectedLoan.InterestRate, expectedLoan.TermWeeks,
 		expectedLoan.WeeklyPaymentAmount, expectedLoan.TotalLoanAmount, expectedLoan.StartDate,
-		expectedLoan.Status, expectedLoan.CreatedAt, expectedLoan.UpdatedAt,
+		expectedLoan.Status, expectedLoan.DaysPastDue, expectedLoan.ExternalRef, expectedLoan.CreatedAt, expectedLoan.UpdatedAt,
+		expectedLoan.NextDueDate, expectedLoan.NextDueAmount, expectedLoan.InstallmentsPaid, expectedLoan.InstallmentsRemaining,
+		expectedLoan.Structure.Type, expectedLoan.Structure.InterestOnlyWeeks, expectedLoan.Structure.BalloonAmount,
 	)
 
 	mockDB.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(loanID).WillReturnRows(rows)
@@ -386,7 +440,8 @@ func TestLoanRepositoryGetLoanByIDNotFound(t *testing.T) {
 	loanID := int64(999)
 
 	query := `
-        SELECT id, principal_amount, interest_rate, term_weeks, weekly_payment_amount, total_loan_amount, start_date, status, created_at, updated_at
+        SELECT id, public_id, principal_amount, interest_rate, term_weeks, weekly_payment_amount, total_loan_amount, start_date, status, days_past_due, external_ref, created_at, updated_at,
+               next_due_date, next_due_amount, installments_paid, installments_remaining, schedule_type, interest_only_weeks, balloon_amount
         FROM loans
         WHERE id = $1`
 
@@ -409,7 +464,8 @@ func TestLoanRepositoryGetLoanByIDDBError(t *testing.T) {
 	dbErr := errors.New("connection failure")
 
 	query := `
-        SELECT id, principal_amount, interest_rate, term_weeks, weekly_payment_amount, total_loan_amount, start_date, status, created_at, updated_at
+        SELECT id, public_id, principal_amount, interest_rate, term_weeks, weekly_payment_amount, total_loan_amount, start_date, status, days_past_due, external_ref, created_at, updated_at,
+               next_due_date, next_due_amount, installments_paid, installments_remaining, schedule_type, interest_only_weeks, balloon_amount
         FROM loans
         WHERE id = $1`
 
@@ -426,6 +482,179 @@ func TestLoanRepositoryGetLoanByIDDBError(t *testing.T) {
 	assert.NoError(t, mockPool.ExpectationsWereMet())
 }
 
+func TestLoanRepositoryGetLoanWithSchedule(t *testing.T) {
+	loanQuery := `
+        SELECT id, public_id, principal_amount, interest_rate, term_weeks, weekly_payment_amount, total_loan_amount, start_date, status, days_past_due, external_ref, created_at, updated_at,
+               next_due_date, next_due_amount, installments_paid, installments_remaining, schedule_type, interest_only_weeks, balloon_amount
+        FROM loans
+        WHERE id = $1`
+	scheduleQuery := `
+        SELECT id, loan_id, week_number, due_date, due_amount, paid_amount, payment_date, status, created_at, updated_at
+        FROM loan_schedule
+        WHERE loan_id = $1
+        ORDER BY week_number ASC`
+	loanCols := []string{
+		"id", "public_id", "principal_amount", "interest_rate", "term_weeks", "weekly_payment_amount",
+		"total_loan_amount", "start_date", "status", "days_past_due", "external_ref", "created_at", "updated_at",
+		"next_due_date", "next_due_amount", "installments_paid", "installments_remaining", "schedule_type", "interest_only_weeks", "balloon_amount",
+	}
+	scheduleCols := []string{"id", "loan_id", "week_number", "due_date", "due_amount", "paid_amount", "payment_date", "status", "created_at", "updated_at"}
+	loanID := int64(1)
+	now := time.Now()
+	dueDate := now.AddDate(0, 0, 7)
+
+	t.Run("reads the loan and its schedule in one batch", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+
+		batch := mockPool.ExpectBatch()
+		batch.ExpectQuery(regexp.QuoteMeta(loanQuery)).WithArgs(loanID).
+			WillReturnRows(pgxmock.NewRows(loanCols).AddRow(
+				loanID, uuid.Nil, 1000.0, 0.1, 10, 110.0, 1100.0, now, loan.StatusActive, 0, (*string)(nil), now, now,
+				&dueDate, 110.0, 0, 1, loan.ScheduleStandard, 0, 0.0))
+		batch.ExpectQuery(regexp.QuoteMeta(scheduleQuery)).WithArgs(loanID).
+			WillReturnRows(pgxmock.NewRows(scheduleCols).AddRow(
+				int64(7), loanID, 1, dueDate, 110.0, 0.0, (*time.Time)(nil), loan.PaymentStatusPending, now, now))
+
+		result, err := repo.GetLoanWithSchedule(ctx, loanID)
+
+		require.NoError(t, err)
+		assert.Equal(t, loanID, result.ID)
+		assert.Equal(t, 1, result.InstallmentsRemaining)
+		assert.Equal(t, []loan.ScheduleEntry{{ID: 7, LoanID: loanID, WeekNumber: 1, DueDate: dueDate, DueAmount: 110.0,
+			Status: loan.PaymentStatusPending, CreatedAt: now, UpdatedAt: now}}, result.Schedule)
+		assert.NoError(t, mockPool.ExpectationsWereMet())
+	})
+
+	t.Run("reports a missing loan as not found", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+
+		batch := mockPool.ExpectBatch()
+		batch.ExpectQuery(regexp.QuoteMeta(loanQuery)).WithArgs(loanID).WillReturnRows(pgxmock.NewRows(loanCols))
+		batch.ExpectQuery(regexp.QuoteMeta(scheduleQuery)).WithArgs(loanID).WillReturnRows(pgxmock.NewRows(scheduleCols))
+
+		result, err := repo.GetLoanWithSchedule(ctx, loanID)
+
+		assert.Nil(t, result)
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+
+	t.Run("returns the schedule query's error", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+
+		batch := mockPool.ExpectBatch()
+		batch.ExpectQuery(regexp.QuoteMeta(loanQuery)).WithArgs(loanID).
+			WillReturnRows(pgxmock.NewRows(loanCols).AddRow(
+				loanID, uuid.Nil, 1000.0, 0.1, 10, 110.0, 1100.0, now, loan.StatusActive, 0, (*string)(nil), now, now,
+				&dueDate, 110.0, 0, 1, loan.ScheduleStandard, 0, 0.0))
+		batch.ExpectQuery(regexp.QuoteMeta(scheduleQuery)).WithArgs(loanID).WillReturnError(errors.New("canceling statement due to statement timeout"))
+
+		result, err := repo.GetLoanWithSchedule(ctx, loanID)
+
+		assert.Nil(t, result)
+		assert.ErrorIs(t, err, apperrors.ErrDatabase)
+		assert.ErrorContains(t, err, "statement timeout")
+	})
+}
+
+func TestLoanRepositoryGetLoanByPublicIDSuccess(t *testing.T) {
+	ctx, repo, mockPool := setupLoanRepo(t)
+	defer mockPool.Close()
+	publicID := uuid.New()
+	now := time.Now()
+
+	query := `
+        SELECT id, public_id, principal_amount, interest_rate, term_weeks, weekly_payment_amount, total_loan_amount, start_date, status, days_past_due, external_ref, created_at, updated_at,
+               next_due_date, next_due_amount, installments_paid, installments_remaining, schedule_type, interest_only_weeks, balloon_amount
+        FROM loans
+        WHERE public_id = $1`
+	rows := pgxmock.NewRows([]string{
+		"id", "public_id", "principal_amount", "interest_rate", "term_weeks", "weekly_payment_amount",
+		"total_loan_amount", "start_date", "status", "days_past_due", "external_ref", "created_at", "updated_at",
+		"next_due_date", "next_due_amount", "installments_paid", "installments_remaining", "schedule_type", "interest_only_weeks", "balloon_amount",
+	}).AddRow(int64(8), publicID, loan.Money(1000), loan.Money(5), 10, loan.Money(105), loan.Money(1050), now, loan.StatusActive, 0, nil, now, now, &now, loan.Money(105), 0, 10, loan.ScheduleStandard, 0, 0.0)
+
+	mockPool.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(publicID).WillReturnRows(rows)
+
+	resultLoan, err := repo.GetLoanByPublicID(ctx, publicID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(8), resultLoan.ID)
+	assert.Equal(t, publicID, resultLoan.PublicID)
+	assert.Equal(t, now, *resultLoan.NextDueDate)
+	assert.Equal(t, 105.0, resultLoan.NextDueAmount)
+	assert.Equal(t, 10, resultLoan.InstallmentsRemaining)
+	assert.NoError(t, mockPool.ExpectationsWereMet())
+}
+
+func TestLoanRepositoryGetLoanByPublicIDNotFound(t *testing.T) {
+	ctx, repo, mockPool := setupLoanRepo(t)
+	defer mockPool.Close()
+	publicID := uuid.New()
+
+	query := `
+        SELECT id, public_id, principal_amount, interest_rate, term_weeks, weekly_payment_amount, total_loan_amount, start_date, status, days_past_due, external_ref, created_at, updated_at,
+               next_due_date, next_due_amount, installments_paid, installments_remaining, schedule_type, interest_only_weeks, balloon_amount
+        FROM loans
+        WHERE public_id = $1`
+
+	mockPool.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(publicID).WillReturnError(pgx.ErrNoRows)
+
+	resultLoan, err := repo.GetLoanByPublicID(ctx, publicID)
+
+	assert.Nil(t, resultLoan)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	assert.NoError(t, mockPool.ExpectationsWereMet())
+}
+
+func TestLoanRepositoryGetLoanByExternalRefSuccess(t *testing.T) {
+	ctx, repo, mockPool := setupLoanRepo(t)
+	defer mockPool.Close()
+	externalRef := "LOS-42"
+	now := time.Now()
+
+	query := `
+        SELECT id, public_id, principal_amount, interest_rate, term_weeks, weekly_payment_amount, total_loan_amount, start_date, status, days_past_due, external_ref, created_at, updated_at,
+               next_due_date, next_due_amount, installments_paid, installments_remaining, schedule_type, interest_only_weeks, balloon_amount
+        FROM loans
+        WHERE external_ref = $1`
+	rows := pgxmock.NewRows([]string{
+		"id", "public_id", "principal_amount", "interest_rate", "term_weeks", "weekly_payment_amount",
+		"total_loan_amount", "start_date", "status", "days_past_due", "external_ref", "created_at", "updated_at",
+		"next_due_date", "next_due_amount", "installments_paid", "installments_remaining", "schedule_type", "interest_only_weeks", "balloon_amount",
+	}).AddRow(int64(7), uuid.New(), loan.Money(1000), loan.Money(5), 10, loan.Money(105), loan.Money(1050), now, loan.StatusActive, 0, &externalRef, now, now, &now, loan.Money(105), 0, 10, loan.ScheduleStandard, 0, 0.0)
+
+	mockPool.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(externalRef).WillReturnRows(rows)
+
+	resultLoan, err := repo.GetLoanByExternalRef(ctx, externalRef)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), resultLoan.ID)
+	assert.Equal(t, externalRef, *resultLoan.ExternalRef)
+	assert.NoError(t, mockPool.ExpectationsWereMet())
+}
+
+func TestLoanRepositoryGetLoanByExternalRefNotFound(t *testing.T) {
+	ctx, repo, mockPool := setupLoanRepo(t)
+	defer mockPool.Close()
+
+	query := `
+        SELECT id, public_id, principal_amount, interest_rate, term_weeks, weekly_payment_amount, total_loan_amount, start_date, status, days_past_due, external_ref, created_at, updated_at,
+               next_due_date, next_due_amount, installments_paid, installments_remaining, schedule_type, interest_only_weeks, balloon_amount
+        FROM loans
+        WHERE external_ref = $1`
+
+	mockPool.ExpectQuery(regexp.QuoteMeta(query)).WithArgs("missing").WillReturnError(pgx.ErrNoRows)
+
+	resultLoan, err := repo.GetLoanByExternalRef(ctx, "missing")
+
+	assert.Nil(t, resultLoan)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	assert.NoError(t, mockPool.ExpectationsWereMet())
+}
+
 func TestLoanRepositoryGetScheduleByLoanIDSuccess(t *testing.T) {
 	ctx, repo, mockPool := setupLoanRepo(t)
 	defer mockPool.Close()
@@ -553,7 +782,7 @@ func TestGetLastTwoDueUnpaidSchedulesSuccess(t *testing.T) {
         SELECT id, loan_id, week_number, due_date, due_amount, paid_amount, payment_date, status, created_at, updated_at
         FROM loan_schedule
         WHERE loan_id = $1 AND status not in ('PAID')
-		AND due_date < NOW()
+		AND due_date < $2
         ORDER BY due_date DESC
         LIMIT 2`
 
@@ -563,7 +792,7 @@ func TestGetLastTwoDueUnpaidSchedulesSuccess(t *testing.T) {
 		rows.AddRow(entry.ID, entry.LoanID, entry.WeekNumber, entry.DueDate, entry.DueAmount, entry.PaidAmount, entry.PaymentDate, entry.Status, entry.CreatedAt, entry.UpdatedAt)
 	}
 
-	mockPool.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(loanID).WillReturnRows(rows)
+	mockPool.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(loanID, testClock.Now()).WillReturnRows(rows)
 
 	schedule, err := repo.GetLastTwoDueUnpaidSchedules(ctx, loanID)
 
@@ -572,6 +801,37 @@ func TestGetLastTwoDueUnpaidSchedulesSuccess(t *testing.T) {
 	assert.NoError(t, mockPool.ExpectationsWereMet())
 }
 
+func TestLoanRepositoryLockLoanForPayment(t *testing.T) {
+	ctx, repo, mockPool := setupLoanRepo(t)
+	defer mockPool.Close()
+
+	t.Run("takes the lock", func(t *testing.T) {
+		mockPool.ExpectQuery(regexp.QuoteMeta(lockLoanForPaymentQuery)).WithArgs(int64(1)).
+			WillReturnRows(pgxmock.NewRows([]string{"pg_try_advisory_xact_lock"}).AddRow(true))
+
+		assert.NoError(t, inTx(repo, mockPool).LockLoanForPayment(ctx, 1))
+	})
+
+	t.Run("reports a lock held by another payment", func(t *testing.T) {
+		mockPool.ExpectQuery(regexp.QuoteMeta(lockLoanForPaymentQuery)).WithArgs(int64(1)).
+			WillReturnRows(pgxmock.NewRows([]string{"pg_try_advisory_xact_lock"}).AddRow(false))
+
+		err := inTx(repo, mockPool).LockLoanForPayment(ctx, 1)
+
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+		assert.ErrorContains(t, err, "another payment for loan 1 is in progress")
+	})
+
+	t.Run("wraps database errors", func(t *testing.T) {
+		mockPool.ExpectQuery(regexp.QuoteMeta(lockLoanForPaymentQuery)).WithArgs(int64(1)).
+			WillReturnError(errors.New("connection reset"))
+
+		assert.ErrorIs(t, inTx(repo, mockPool).LockLoanForPayment(ctx, 1), apperrors.ErrDatabase)
+	})
+
+	assert.NoError(t, mockPool.ExpectationsWereMet())
+}
+
 func TestLoanRepositoryFindOldestUnpaidEntryForUpdateSuccess(t *testing.T) {
 	ctx, repo, mockPool := setupLoanRepo(t)
 	defer mockPool.Close()
@@ -601,7 +861,7 @@ func TestLoanRepositoryFindOldestUnpaidEntryForUpdateSuccess(t *testing.T) {
 
 	mockPool.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(loanID).WillReturnRows(rows)
 
-	entry, err := repo.FindOldestUnpaidEntryForUpdate(ctx, mockPool, loanID)
+	entry, err := inTx(repo, mockPool).FindOldestUnpaidEntryForUpdate(ctx, loanID)
 
 	assert.NoError(t, err)
 	require.NotNil(t, entry)
@@ -626,14 +886,14 @@ func TestLoanRepositoryFindOldestUnpaidEntryForUpdateNotFound(t *testing.T) {
 		WithArgs(loanID).
 		WillReturnError(pgx.ErrNoRows)
 
-	entry, err := repo.FindOldestUnpaidEntryForUpdate(ctx, mockPool, loanID)
+	entry, err := inTx(repo, mockPool).FindOldestUnpaidEntryForUpdate(ctx, loanID)
 
 	assert.Error(t, err)
 	assert.Nil(t, entry)
 	assert.ErrorIs(t, err, apperrors.ErrNotFound)
 }
 
-func TestUpdateScheduleEntryInTxSuccess(t *testing.T) {
+func TestUpdateScheduleEntrySuccess(t *testing.T) {
 	ctx, repo, mockPool := setupLoanRepo(t)
 	defer mockPool.Close()
 
@@ -648,19 +908,20 @@ func TestUpdateScheduleEntryInTxSuccess(t *testing.T) {
 
 	sql := `
         UPDATE loan_schedule
-        SET paid_amount = $1, payment_date = $2, status = $3, updated_at = NOW()
-        WHERE id = $4 AND loan_id = $5`
+        SET paid_amount = $1, payment_date = $2, status = $3, updated_at = $4
+        WHERE id = $5 AND loan_id = $6`
 
 	mockPool.ExpectExec(regexp.QuoteMeta(sql)).
-		WithArgs(entryToUpdate.PaidAmount, entryToUpdate.PaymentDate, entryToUpdate.Status, entryToUpdate.ID, entryToUpdate.LoanID).
+		WithArgs(entryToUpdate.PaidAmount, entryToUpdate.PaymentDate, entryToUpdate.Status, testClock.Now(), entryToUpdate.ID, entryToUpdate.LoanID).
 		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
 
-	err := repo.UpdateScheduleEntryInTx(ctx, mockPool, entryToUpdate)
+	err := inTx(repo, mockPool).UpdateScheduleEntry(ctx, entryToUpdate)
 
 	assert.NoError(t, err)
+	assert.Equal(t, testClock.Now(), entryToUpdate.UpdatedAt)
 }
 
-func TestLoanRepositoryUpdateScheduleEntryInTxErrorDB(t *testing.T) {
+func TestLoanRepositoryUpdateScheduleEntryErrorDB(t *testing.T) {
 	ctx, repo, mockPool := setupLoanRepo(t)
 	defer mockPool.Close()
 	entryToUpdate := &loan.ScheduleEntry{ID: 1, LoanID: 10 /* ... */}
@@ -668,60 +929,104 @@ func TestLoanRepositoryUpdateScheduleEntryInTxErrorDB(t *testing.T) {
 
 	sql := `
         UPDATE loan_schedule
-        SET paid_amount = $1, payment_date = $2, status = $3, updated_at = NOW()
-        WHERE id = $4 AND loan_id = $5`
+        SET paid_amount = $1, payment_date = $2, status = $3, updated_at = $4
+        WHERE id = $5 AND loan_id = $6`
 
 	mockPool.ExpectExec(regexp.QuoteMeta(sql)).
-		WithArgs(entryToUpdate.PaidAmount, entryToUpdate.PaymentDate, entryToUpdate.Status, entryToUpdate.ID, entryToUpdate.LoanID).
+		WithArgs(entryToUpdate.PaidAmount, entryToUpdate.PaymentDate, entryToUpdate.Status, testClock.Now(), entryToUpdate.ID, entryToUpdate.LoanID).
 		WillReturnError(dbErr)
 
-	err := repo.UpdateScheduleEntryInTx(ctx, mockPool, entryToUpdate)
+	err := inTx(repo, mockPool).UpdateScheduleEntry(ctx, entryToUpdate)
 
 	assert.Error(t, err)
 	assert.ErrorIs(t, err, apperrors.ErrDatabase)
 	assert.ErrorContains(t, err, dbErr.Error())
 }
 
-func TestLoanRepositoryUpdateScheduleEntryInTxErrorRowsAffectedZero(t *testing.T) {
+func TestLoanRepositoryUpdateScheduleEntryErrorRowsAffectedZero(t *testing.T) {
 	ctx, repo, mockPool := setupLoanRepo(t)
 	defer mockPool.Close()
 	entryToUpdate := &loan.ScheduleEntry{ID: 1, LoanID: 10 /* ... */}
 
 	sql := `
         UPDATE loan_schedule
-        SET paid_amount = $1, payment_date = $2, status = $3, updated_at = NOW()
-        WHERE id = $4 AND loan_id = $5`
+        SET paid_amount = $1, payment_date = $2, status = $3, updated_at = $4
+        WHERE id = $5 AND loan_id = $6`
 
 	mockPool.ExpectExec(regexp.QuoteMeta(sql)).
-		WithArgs(entryToUpdate.PaidAmount, entryToUpdate.PaymentDate, entryToUpdate.Status, entryToUpdate.ID, entryToUpdate.LoanID).
+		WithArgs(entryToUpdate.PaidAmount, entryToUpdate.PaymentDate, entryToUpdate.Status, testClock.Now(), entryToUpdate.ID, entryToUpdate.LoanID).
 		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
 
-	err := repo.UpdateScheduleEntryInTx(ctx, mockPool, entryToUpdate)
+	err := inTx(repo, mockPool).UpdateScheduleEntry(ctx, entryToUpdate)
 
 	assert.Error(t, err)
 	assert.ErrorIs(t, err, apperrors.ErrDatabase)
 	assert.ErrorContains(t, err, "affected zero rows")
 }
 
-func TestLoanRepositoryUpdateLoanStatusInTxSuccess(t *testing.T) {
+func TestLoanRepositoryUpdateLoanStatusSuccess(t *testing.T) {
 	ctx, repo, mockPool := setupLoanRepo(t)
 	defer mockPool.Close()
 
 	loanID := int64(10)
 	newStatus := loan.StatusPaidOff
 
-	sql := `UPDATE loans SET status = $1, updated_at = NOW() WHERE id = $2`
+	sql := `UPDATE loans SET status = $1, days_past_due = CASE WHEN $1 = 'PAID_OFF' THEN 0 ELSE days_past_due END, updated_at = $2 WHERE id = $3`
 
 	mockPool.ExpectExec(regexp.QuoteMeta(sql)).
-		WithArgs(newStatus, loanID).
+		WithArgs(newStatus, testClock.Now(), loanID).
 		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
 
-	err := repo.UpdateLoanStatusInTx(ctx, mockPool, loanID, newStatus)
+	err := inTx(repo, mockPool).UpdateLoanStatus(ctx, loanID, newStatus)
 
 	assert.NoError(t, err)
 }
 
-func TestCheckIfAllPaymentsMadeInTxTrue(t *testing.T) {
+func TestLoanRepositoryRecordPayment(t *testing.T) {
+	sql := `
+        INSERT INTO payments (loan_id, schedule_id, fee_id, amount, channel, reference, collector_id, paid_at, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+        RETURNING id, created_at`
+	reference := "TRF-1"
+	scheduleID := int64(3)
+	paidAt := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	newPayment := func() *loan.Payment {
+		return &loan.Payment{LoanID: 10, ScheduleID: &scheduleID, Amount: 110, Channel: loan.ChannelBankTransfer, Reference: &reference, PaidAt: paidAt}
+	}
+
+	t.Run("returns the ledger ID", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+		payment := newPayment()
+
+		mockPool.ExpectQuery(regexp.QuoteMeta(sql)).
+			WithArgs(int64(10), &scheduleID, (*int64)(nil), 110.0, loan.ChannelBankTransfer, &reference, (*string)(nil), paidAt, testClock.Now()).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "created_at"}).AddRow(int64(7), testClock.Now()))
+
+		err := inTx(repo, mockPool).RecordPayment(ctx, payment)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(7), payment.ID)
+		assert.Equal(t, testClock.Now(), payment.CreatedAt)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("reports a reference posted twice", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+
+		mockPool.ExpectQuery(regexp.QuoteMeta(sql)).
+			WithArgs(int64(10), &scheduleID, (*int64)(nil), 110.0, loan.ChannelBankTransfer, &reference, (*string)(nil), paidAt, testClock.Now()).
+			WillReturnError(&pgconn.PgError{Code: "23505", ConstraintName: "uq_payments_channel_reference"})
+
+		err := inTx(repo, mockPool).RecordPayment(ctx, newPayment())
+
+		assert.ErrorIs(t, err, apperrors.ErrAlreadyExists)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+}
+
+func TestCheckIfAllPaymentsMadeTrue(t *testing.T) {
 	ctx, repo, mockPool := setupLoanRepo(t)
 	defer mockPool.Close()
 	loanID := int64(10)
@@ -731,29 +1036,30 @@ func TestCheckIfAllPaymentsMadeInTxTrue(t *testing.T) {
 
 	mockPool.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(loanID).WillReturnRows(rows)
 
-	allPaid, err := repo.CheckIfAllPaymentsMadeInTx(ctx, mockPool, loanID)
+	allPaid, err := inTx(repo, mockPool).CheckIfAllPaymentsMade(ctx, loanID)
 
 	assert.NoError(t, err)
 	assert.True(t, allPaid)
 }
 
-func TestLoanRepositoryCheckIfAllPaymentsMadeInTxFalse(t *testing.T) {
+func TestLoanRepositoryCheckIfAllPaymentsMadeFalse(t *testing.T) {
 	ctx, repo, mockPool := setupLoanRepo(t)
 	defer mockPool.Close()
 	loanID := int64(10)
 
-	query := `SELECT COUNT(*) FROM loan_schedule WHERE loan_id = $1 AND status != 'PAID'`
-	rows := pgxmock.NewRows([]string{"count"}).AddRow(2)
+	// The schedule is paid but a fee is still owed.
+	query := `+ (SELECT COUNT(*) FROM fees f` + openFeesWhere + `)`
+	rows := pgxmock.NewRows([]string{"count"}).AddRow(1)
 
 	mockPool.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(loanID).WillReturnRows(rows)
 
-	allPaid, err := repo.CheckIfAllPaymentsMadeInTx(ctx, mockPool, loanID)
+	allPaid, err := inTx(repo, mockPool).CheckIfAllPaymentsMade(ctx, loanID)
 
 	assert.NoError(t, err)
 	assert.False(t, allPaid)
 }
 
-func TestLoanRepositoryCheckIfAllPaymentsMadeInTxDBError(t *testing.T) {
+func TestLoanRepositoryCheckIfAllPaymentsMadeDBError(t *testing.T) {
 	ctx, repo, mockPool := setupLoanRepo(t)
 	defer mockPool.Close()
 	loanID := int64(10)
@@ -762,7 +1068,7 @@ func TestLoanRepositoryCheckIfAllPaymentsMadeInTxDBError(t *testing.T) {
 	query := `SELECT COUNT(*) FROM loan_schedule WHERE loan_id = $1 AND status != 'PAID'`
 	mockPool.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(loanID).WillReturnError(dbErr)
 
-	allPaid, err := repo.CheckIfAllPaymentsMadeInTx(ctx, mockPool, loanID)
+	allPaid, err := inTx(repo, mockPool).CheckIfAllPaymentsMade(ctx, loanID)
 
 	assert.Error(t, err)
 	assert.False(t, allPaid)
@@ -770,107 +1076,341 @@ func TestLoanRepositoryCheckIfAllPaymentsMadeInTxDBError(t *testing.T) {
 	assert.ErrorContains(t, err, dbErr.Error())
 }
 
-func TestLoanRepositoryGetTotalOutstandingAmountSuccessPositive(t *testing.T) {
+func TestLoanRepositoryListBalanceItems(t *testing.T) {
 	ctx, repo, mockPool := setupLoanRepo(t)
 	defer mockPool.Close()
 	loanID := int64(1)
-	expectedAmount := 210.50
 
 	query := `
-        SELECT COALESCE(SUM(due_amount - paid_amount), 0.00)
-        FROM loan_schedule
-        WHERE loan_id = $1 AND status != 'PAID'`
+        SELECT amount, end_to_end_id
+        FROM direct_debit_instructions
+        WHERE loan_id = $1 AND status = 'UNAPPLIED'
+        ORDER BY id`
 
-	rows := pgxmock.NewRows([]string{"coalesce"}).AddRow(expectedAmount)
+	rows := pgxmock.NewRows([]string{"amount", "end_to_end_id"}).AddRow(110.0, "E2E1")
 	mockPool.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(loanID).WillReturnRows(rows)
+	mockPool.ExpectQuery(regexp.QuoteMeta(listOpenFeesQuery)).WithArgs(loanID).
+		WillReturnRows(pgxmock.NewRows(feeColumnNames).
+			AddRow(append([]any{int64(4), loanID, loan.FeeBounce, 25.0, "direct debit returned", nil, testClock.Now(), nil}, untaxed...)...).
+			AddRow(append([]any{int64(5), loanID, loan.FeeLegal, 50.0, "court filing", nil, testClock.Now(), nil}, taxLineValues(9, "ID", 0.11, 50, 5.5)...)...))
 
-	amount, err := repo.GetTotalOutstandingAmount(ctx, loanID)
+	items, err := repo.ListBalanceItems(ctx, loanID)
 
 	assert.NoError(t, err)
-	assert.Equal(t, expectedAmount, amount)
+	assert.Equal(t, []loan.BalanceItem{
+		{Kind: loan.BalanceSuspense, Amount: 110, Description: "direct debit E2E1 collected but not posted"},
+		{Kind: loan.BalanceFee, Amount: 25, Description: "Bounce fee: direct debit returned"},
+		{Kind: loan.BalanceFee, Amount: 50, Description: "Legal fee: court filing"},
+		{Kind: loan.BalanceTax, Amount: 5.5, Description: "Tax on Legal fee: court filing"},
+	}, items)
 	assert.NoError(t, mockPool.ExpectationsWereMet())
 }
 
-func TestLoanRepositoryGetTotalOutstandingAmountSuccessZero(t *testing.T) {
+func TestLoanRepositoryListBalanceItemsNone(t *testing.T) {
 	ctx, repo, mockPool := setupLoanRepo(t)
 	defer mockPool.Close()
-	loanID := int64(1)
-	expectedAmount := 0.00
 
-	query := `
-        SELECT COALESCE(SUM(due_amount - paid_amount), 0.00)
-        FROM loan_schedule
-        WHERE loan_id = $1 AND status != 'PAID'`
+	mockPool.ExpectQuery(`FROM direct_debit_instructions`).WithArgs(int64(1)).
+		WillReturnRows(pgxmock.NewRows([]string{"amount", "end_to_end_id"}))
+	mockPool.ExpectQuery(regexp.QuoteMeta(listOpenFeesQuery)).WithArgs(int64(1)).WillReturnRows(pgxmock.NewRows(feeColumnNames))
 
-	rows := pgxmock.NewRows([]string{"coalesce"}).AddRow(expectedAmount)
-	mockPool.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(loanID).WillReturnRows(rows)
+	items, err := repo.ListBalanceItems(ctx, 1)
 
-	amount, err := repo.GetTotalOutstandingAmount(ctx, loanID)
 	assert.NoError(t, err)
-	assert.Equal(t, expectedAmount, amount)
+	assert.NotNil(t, items)
+	assert.Empty(t, items)
 	assert.NoError(t, mockPool.ExpectationsWereMet())
 }
 
-func TestLoanRepositoryGetTotalOutstandingAmountSuccessNoRowsIsNull(t *testing.T) {
+func TestLoanRepositoryListBalanceItemsDBError(t *testing.T) {
 	ctx, repo, mockPool := setupLoanRepo(t)
 	defer mockPool.Close()
-	loanID := int64(1)
-
-	query := `
-		SELECT COALESCE(SUM(due_amount - paid_amount), 0.00)
-		FROM loan_schedule
-		WHERE loan_id = $1 AND status != 'PAID'`
+	dbErr := errors.New("balance query failed")
 
-	rows := pgxmock.NewRows([]string{"coalesce"})
-	mockPool.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(loanID).WillReturnRows(rows)
+	mockPool.ExpectQuery(`FROM direct_debit_instructions`).WithArgs(int64(1)).WillReturnError(dbErr)
 
-	amount, err := repo.GetTotalOutstandingAmount(ctx, loanID)
+	items, err := repo.ListBalanceItems(ctx, 1)
 
-	assert.NoError(t, err)
-	assert.Equal(t, 0.00, amount)
+	assert.Nil(t, items)
+	assert.ErrorIs(t, err, apperrors.ErrDatabase)
+	assert.ErrorContains(t, err, dbErr.Error())
 	assert.NoError(t, mockPool.ExpectationsWereMet())
 }
 
-func TestLoanRepositoryGetTotalOutstandingAmountNegativeSumReturnsZero(t *testing.T) {
-	ctx, repo, mockPool := setupLoanRepo(t)
-	defer mockPool.Close()
-	loanID := int64(1)
-	dbReturnedAmount := -50.25
+func TestLoanRepositoryGetLastModified(t *testing.T) {
+	query := getLastModifiedQuery
 
-	query := `
-        SELECT COALESCE(SUM(due_amount - paid_amount), 0.00)
-        FROM loan_schedule
-        WHERE loan_id = $1 AND status != 'PAID'`
+	t.Run("returns the latest change", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+		lastModified := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
 
-	rows := pgxmock.NewRows([]string{"coalesce"}).AddRow(dbReturnedAmount)
-	mockPool.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(loanID).WillReturnRows(rows)
+		mockPool.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(int64(1)).
+			WillReturnRows(pgxmock.NewRows([]string{"greatest"}).AddRow(lastModified))
 
-	amount, err := repo.GetTotalOutstandingAmount(ctx, loanID)
+		got, err := repo.GetLastModified(ctx, 1)
 
-	assert.NoError(t, err)
+		assert.NoError(t, err)
+		assert.Equal(t, lastModified, got)
+		assert.NoError(t, mockPool.ExpectationsWereMet())
+	})
 
-	assert.Equal(t, 0.00, amount)
-	assert.NoError(t, mockPool.ExpectationsWereMet())
+	t.Run("returns not found for an unknown loan", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+
+		mockPool.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(int64(2)).WillReturnError(pgx.ErrNoRows)
+
+		_, err := repo.GetLastModified(ctx, 2)
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+		assert.NoError(t, mockPool.ExpectationsWereMet())
+	})
 }
 
-func TestLoanRepositoryGetTotalOutstandingAmountDBError(t *testing.T) {
-	ctx, repo, mockPool := setupLoanRepo(t)
-	defer mockPool.Close()
-	loanID := int64(1)
-	dbErr := errors.New("sum query failed")
+func TestLoanRepositorySumPaymentsByChannel(t *testing.T) {
+	query := `
+        SELECT channel, COUNT(*), COALESCE(SUM(amount), 0)
+        FROM (
+            SELECT channel, amount FROM payments WHERE paid_at >= $1 AND paid_at < $2
+            UNION ALL
+            SELECT channel, amount FROM payments_archive WHERE paid_at >= $1 AND paid_at < $2
+            UNION ALL
+            SELECT channel, amount FROM prepayments WHERE paid_at >= $1 AND paid_at < $2
+            UNION ALL
+            SELECT channel, amount FROM prepayments_archive WHERE paid_at >= $1 AND paid_at < $2
+        ) received
+        GROUP BY channel
+        ORDER BY channel`
+	from := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC)
+
+	t.Run("returns one row per channel", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+
+		mockPool.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(from, to).
+			WillReturnRows(pgxmock.NewRows([]string{"channel", "count", "coalesce"}).
+				AddRow(loan.ChannelCash, 2, 220.0).
+				AddRow(loan.ChannelDirectDebit, 1, 110.0))
+
+		totals, err := repo.SumPaymentsByChannel(ctx, from, to)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []loan.ChannelCollections{
+			{Channel: loan.ChannelCash, Payments: 2, Amount: 220},
+			{Channel: loan.ChannelDirectDebit, Payments: 1, Amount: 110},
+		}, totals)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("wraps database errors", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+
+		mockPool.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(from, to).WillReturnError(errors.New("connection reset"))
+
+		_, err := repo.SumPaymentsByChannel(ctx, from, to)
+
+		assert.ErrorIs(t, err, apperrors.ErrDatabase)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+}
 
+func TestLoanRepositoryListPayments(t *testing.T) {
 	query := `
-        SELECT COALESCE(SUM(due_amount - paid_amount), 0.00)
-        FROM loan_schedule
-        WHERE loan_id = $1 AND status != 'PAID'`
+        SELECT id, loan_id, schedule_id, fee_id, amount, channel, reference, collector_id, paid_at, created_at
+        FROM payments WHERE loan_id = $1
+        ORDER BY paid_at DESC, id DESC
+        LIMIT $2`
+	paidAt := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	columns := []string{"id", "loan_id", "schedule_id", "fee_id", "amount", "channel", "reference", "collector_id", "paid_at", "created_at"}
+
+	t.Run("returns the latest payments", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+		reference := "TRF-1"
+		scheduleID, feeID := int64(12), int64(6)
+
+		mockPool.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(int64(1), 5).
+			WillReturnRows(pgxmock.NewRows(columns).
+				AddRow(int64(4), int64(1), nil, &feeID, 27.75, loan.ChannelCash, nil, nil, paidAt, paidAt).
+				AddRow(int64(3), int64(1), &scheduleID, nil, 110.0, loan.ChannelBankTransfer, &reference, nil, paidAt, paidAt))
+
+		payments, err := repo.ListPayments(ctx, 1, 5)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []loan.Payment{
+			{ID: 4, LoanID: 1, FeeID: &feeID, Amount: 27.75, Channel: loan.ChannelCash, PaidAt: paidAt, CreatedAt: paidAt},
+			{ID: 3, LoanID: 1, ScheduleID: &scheduleID, Amount: 110, Channel: loan.ChannelBankTransfer, Reference: &reference, PaidAt: paidAt, CreatedAt: paidAt},
+		}, payments)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("wraps database errors", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+
+		mockPool.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(int64(1), 5).WillReturnError(errors.New("connection reset"))
+
+		_, err := repo.ListPayments(ctx, 1, 5)
+
+		assert.ErrorIs(t, err, apperrors.ErrDatabase)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+}
 
-	mockPool.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(loanID).WillReturnError(dbErr)
+func TestLoanRepositorySearchLoans(t *testing.T) {
+	publicID := uuid.New()
+	paidAt := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	customerID, name, reference := int64(7), "Jane Doe", "TRF-1"
 
-	amount, err := repo.GetTotalOutstandingAmount(ctx, loanID)
+	t.Run("searches the ledger by reference and name", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
 
-	assert.Error(t, err)
-	assert.Equal(t, 0.00, amount)
-	assert.ErrorIs(t, err, apperrors.ErrDatabase)
-	assert.ErrorContains(t, err, dbErr.Error())
-	assert.NoError(t, mockPool.ExpectationsWereMet())
+		mockPool.ExpectQuery(regexp.QuoteMeta(`LEFT JOIN customers c ON c.loan_id = l.id WHERE r.reference = $1 AND lower(c.name) LIKE lower($2) ESCAPE '\' ORDER BY r.paid_at DESC, r.id DESC LIMIT $3`)).
+			WithArgs("TRF-1", "%doe%", 20).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "public_id", "status", "days_past_due", "id", "name", "id", "amount", "channel", "reference", "paid_at", "prepayment"}).
+				AddRow(int64(1), publicID, loan.StatusDelinquent, 9, &customerID, &name, int64(4), 110.0, loan.ChannelBankTransfer, &reference, paidAt, true))
+
+		matches, err := repo.SearchLoans(ctx, loan.LoanSearch{Reference: "TRF-1", CustomerName: "doe", Limit: 20})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []loan.SearchMatch{{
+			LoanID: 1, LoanPublicID: publicID, Status: loan.StatusDelinquent, DaysPastDue: 9, CustomerID: &customerID, CustomerName: &name,
+			Payment: &loan.PaymentMatch{ID: 4, Amount: 110, Channel: loan.ChannelBankTransfer, Reference: &reference, PaidAt: paidAt, Prepayment: true},
+		}}, matches)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("bounds a search by amount with the paid window", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+		amount, tolerance := loan.Money(110), loan.Money(1)
+		from, to := paidAt.AddDate(0, 0, -7), paidAt
+
+		mockPool.ExpectQuery(regexp.QuoteMeta(`WHERE r.amount BETWEEN $1 AND $2 AND r.paid_at >= $3 AND r.paid_at < $4 ORDER BY`)).
+			WithArgs(109.0, 111.0, from, to, 5).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}))
+
+		_, err := repo.SearchLoans(ctx, loan.LoanSearch{Amount: &amount, Tolerance: &tolerance, From: from, To: to, Limit: 5})
+
+		assert.NoError(t, err)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("searches customers by name alone", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+
+		mockPool.ExpectQuery(regexp.QuoteMeta(`FROM customers c
+        JOIN loans l ON l.id = c.loan_id WHERE lower(c.name) LIKE lower($1) ESCAPE '\' ORDER BY c.name, l.id LIMIT $2`)).
+			WithArgs(`%50\%%`, 20).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "public_id", "status", "days_past_due", "id", "name"}).
+				AddRow(int64(1), publicID, loan.StatusActive, 0, &customerID, &name))
+
+		matches, err := repo.SearchLoans(ctx, loan.LoanSearch{CustomerName: "50%", Limit: 20})
+
+		assert.NoError(t, err)
+		require.Len(t, matches, 1)
+		assert.Nil(t, matches[0].Payment)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("wraps database errors", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+
+		mockPool.ExpectQuery("FROM customers c").WithArgs("%doe%", 20).WillReturnError(errors.New("connection reset"))
+
+		_, err := repo.SearchLoans(ctx, loan.LoanSearch{CustomerName: "doe", Limit: 20})
+
+		assert.ErrorIs(t, err, apperrors.ErrDatabase)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+}
+
+func TestLoanRepositoryUpdateDaysPastDue(t *testing.T) {
+	query := `UPDATE loans SET days_past_due = $1, updated_at = $2 WHERE id = $3 AND days_past_due <> $1`
+
+	t.Run("writes the new value", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+		mockPool.ExpectExec(regexp.QuoteMeta(query)).
+			WithArgs(45, testClock.Now(), int64(3)).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+		assert.NoError(t, repo.UpdateDaysPastDue(ctx, 3, 45))
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("wraps database errors", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+		mockPool.ExpectExec(regexp.QuoteMeta(query)).
+			WithArgs(45, testClock.Now(), int64(3)).
+			WillReturnError(errors.New("connection reset"))
+
+		assert.ErrorIs(t, repo.UpdateDaysPastDue(ctx, 3, 45), apperrors.ErrDatabase)
+	})
+}
+
+func TestLoanRepositoryStreamLoans(t *testing.T) {
+	selectLoans := `SELECT id, public_id, principal_amount, interest_rate, term_weeks, weekly_payment_amount, total_loan_amount, start_date, status, days_past_due, external_ref, created_at, updated_at,
+               next_due_date, next_due_amount, installments_paid, installments_remaining, schedule_type, interest_only_weeks, balloon_amount
+        FROM loans`
+	query := selectLoans + ` WHERE id > $1 ORDER BY id`
+	columns := []string{"id", "public_id", "principal_amount", "interest_rate", "term_weeks", "weekly_payment_amount", "total_loan_amount", "start_date", "status", "days_past_due", "external_ref", "created_at", "updated_at",
+		"next_due_date", "next_due_amount", "installments_paid", "installments_remaining", "schedule_type", "interest_only_weeks", "balloon_amount"}
+	now := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	rows := func() *pgxmock.Rows {
+		return pgxmock.NewRows(columns).
+			AddRow(int64(6), uuid.New(), 5000.0, 10.0, 50, 110.0, 5500.0, now, loan.StatusActive, 0, nil, now, now, &now, 110.0, 0, 50, loan.ScheduleStandard, 0, 0.0).
+			AddRow(int64(7), uuid.New(), 1000.0, 10.0, 10, 110.0, 1100.0, now, loan.StatusPaidOff, 0, nil, now, now, nil, 0.0, 10, 0, loan.ScheduleBalloon, 0, 300.0)
+	}
+
+	t.Run("hands every row to the callback in order", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+		mockPool.ExpectQuery(regexp.QuoteMeta(selectLoans+` WHERE id > $1 AND days_past_due >= $2 AND status = $3 ORDER BY id`)).
+			WithArgs(int64(5), 30, loan.StatusActive).WillReturnRows(rows())
+
+		var ids []int64
+		err := repo.StreamLoans(ctx, loan.LoanFilter{AfterID: 5, MinDaysPastDue: 30, Status: loan.StatusActive}, func(l *loan.Loan) error {
+			ids = append(ids, l.ID)
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{6, 7}, ids)
+		assert.NoError(t, mockPool.ExpectationsWereMet())
+	})
+
+	t.Run("stops at the first callback error", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+		mockPool.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(int64(0)).WillReturnRows(rows())
+		stop := errors.New("stop")
+
+		calls := 0
+		err := repo.StreamLoans(ctx, loan.LoanFilter{}, func(*loan.Loan) error {
+			calls++
+			return stop
+		})
+
+		assert.ErrorIs(t, err, stop)
+		assert.NotErrorIs(t, err, apperrors.ErrDatabase)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("wraps query errors", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+		mockPool.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(int64(0)).WillReturnError(errors.New("connection reset"))
+
+		err := repo.StreamLoans(ctx, loan.LoanFilter{}, func(*loan.Loan) error { return nil })
+
+		assert.ErrorIs(t, err, apperrors.ErrDatabase)
+	})
 }
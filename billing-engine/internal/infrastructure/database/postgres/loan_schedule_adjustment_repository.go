@@ -0,0 +1,100 @@
+package postgres
+
+import (
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/infrastructure/monitoring"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"fmt"
+	"time"
+)
+
+const scheduleAdjustmentColumns = `id, loan_id, kind, starts_on, weeks, reason, applied_by, applied_at, removed_by, removed_at`
+
+const (
+	getScheduleAdjustmentsQuery   = `SELECT ` + scheduleAdjustmentColumns + ` FROM schedule_adjustments WHERE loan_id = $1 ORDER BY id`
+	insertScheduleAdjustmentQuery = `
+        INSERT INTO schedule_adjustments (loan_id, kind, starts_on, weeks, reason, applied_by, applied_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        RETURNING id`
+	removeScheduleAdjustmentQuery = `
+        UPDATE schedule_adjustments SET removed_by = $3, removed_at = $4
+        WHERE id = $1 AND loan_id = $2 AND removed_at IS NULL`
+	updateLoanTotalQuery = `UPDATE loans SET total_loan_amount = $1, updated_at = $2 WHERE id = $3`
+)
+
+func (r *LoanRepository) GetScheduleAdjustments(ctx context.Context, loanID int64) ([]loan.ScheduleAdjustment, error) {
+	return r.getScheduleAdjustments(ctx, r.db, loanID)
+}
+
+func (t *loanTx) GetScheduleAdjustments(ctx context.Context, loanID int64) ([]loan.ScheduleAdjustment, error) {
+	return t.r.getScheduleAdjustments(ctx, t.tx, loanID)
+}
+
+func (r *LoanRepository) getScheduleAdjustments(ctx context.Context, db rowsQuerier, loanID int64) ([]loan.ScheduleAdjustment, error) {
+	start := time.Now()
+	rows, err := db.Query(ctx, getScheduleAdjustmentsQuery, loanID)
+	if err != nil {
+		monitoring.RecordDBQuery("GetScheduleAdjustments", "error", time.Since(start))
+		r.logger.ErrorContext(ctx, "Failed to read schedule adjustments", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	adjustments := []loan.ScheduleAdjustment{}
+	for rows.Next() {
+		var a loan.ScheduleAdjustment
+		if err := rows.Scan(&a.ID, &a.LoanID, &a.Kind, &a.StartsOn, &a.Weeks, &a.Reason, &a.AppliedBy, &a.AppliedAt, &a.RemovedBy, &a.RemovedAt); err != nil {
+			monitoring.RecordDBQuery("GetScheduleAdjustments", "error", time.Since(start))
+			r.logger.ErrorContext(ctx, "Failed to scan schedule adjustment", "loan_id", loanID, "error", err)
+			return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+		}
+		adjustments = append(adjustments, a)
+	}
+	if err := rows.Err(); err != nil {
+		monitoring.RecordDBQuery("GetScheduleAdjustments", "error", time.Since(start))
+		r.logger.ErrorContext(ctx, "Error iterating schedule adjustments", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	monitoring.RecordDBQuery("GetScheduleAdjustments", "success", time.Since(start))
+	return adjustments, nil
+}
+
+func (t *loanTx) AddScheduleAdjustment(ctx context.Context, plan *loan.AdjustmentPlan) error {
+	a := &plan.Adjustment
+	if err := t.updateLoanTotal(ctx, a.LoanID, plan.TotalLoanAmount); err != nil {
+		return err
+	}
+	err := t.tx.QueryRow(ctx, insertScheduleAdjustmentQuery, a.LoanID, a.Kind, a.StartsOn, a.Weeks, a.Reason, a.AppliedBy, a.AppliedAt).Scan(&a.ID)
+	if err != nil {
+		t.r.logger.ErrorContext(ctx, "Failed to insert schedule adjustment", "loan_id", a.LoanID, "error", err)
+		return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	return nil
+}
+
+func (t *loanTx) RemoveScheduleAdjustment(ctx context.Context, plan *loan.AdjustmentPlan) error {
+	a := &plan.Adjustment
+	cmdTag, err := t.tx.Exec(ctx, removeScheduleAdjustmentQuery, a.ID, a.LoanID, a.RemovedBy, a.RemovedAt)
+	if err != nil {
+		t.r.logger.ErrorContext(ctx, "Failed to remove schedule adjustment", "loan_id", a.LoanID, "adjustment_id", a.ID, "error", err)
+		return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	if cmdTag.RowsAffected() != 1 {
+		return fmt.Errorf("%w: loan %d has no active adjustment %d", apperrors.ErrNotFound, a.LoanID, a.ID)
+	}
+	return t.updateLoanTotal(ctx, a.LoanID, plan.TotalLoanAmount)
+}
+
+func (t *loanTx) updateLoanTotal(ctx context.Context, loanID int64, total loan.Money) error {
+	cmdTag, err := t.tx.Exec(ctx, updateLoanTotalQuery, total, t.r.clock.Now(), loanID)
+	if err != nil {
+		t.r.logger.ErrorContext(ctx, "Failed to update loan total", "loan_id", loanID, "error", err)
+		return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	if cmdTag.RowsAffected() != 1 {
+		t.r.logger.ErrorContext(ctx, "Loan total update affected zero rows", "loan_id", loanID)
+		return fmt.Errorf("%w: loan total update affected zero rows", apperrors.ErrDatabase)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package postgres
+
+import (
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/pkg/apperrors"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var scheduleAdjustmentColumnNames = []string{"id", "loan_id", "kind", "starts_on", "weeks", "reason", "applied_by", "applied_at", "removed_by", "removed_at"}
+
+func TestLoanRepositoryGetScheduleAdjustments(t *testing.T) {
+	ctx, repo, mockPool := setupLoanRepo(t)
+	defer mockPool.Close()
+	admin := "admin"
+	startsOn := time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC)
+	removedAt := testClock.Now()
+
+	mockPool.ExpectQuery(regexp.QuoteMeta(getScheduleAdjustmentsQuery)).WithArgs(int64(1)).
+		WillReturnRows(pgxmock.NewRows(scheduleAdjustmentColumnNames).
+			AddRow(int64(3), int64(1), loan.AdjustmentPaymentHoliday, startsOn, 2, "leave", &admin, testClock.Now(), (*string)(nil), (*time.Time)(nil)).
+			AddRow(int64(4), int64(1), loan.AdjustmentZeroInterest, startsOn, 1, "promotion", &admin, testClock.Now(), &admin, &removedAt))
+	adjustments, err := repo.GetScheduleAdjustments(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, adjustments, 2)
+	assert.Equal(t, loan.AdjustmentPaymentHoliday, adjustments[0].Kind)
+	assert.Equal(t, startsOn, adjustments[0].StartsOn)
+	assert.Nil(t, adjustments[0].RemovedAt)
+	assert.Equal(t, removedAt, *adjustments[1].RemovedAt)
+
+	mockPool.ExpectQuery(regexp.QuoteMeta(getScheduleAdjustmentsQuery)).WithArgs(int64(2)).
+		WillReturnRows(pgxmock.NewRows(scheduleAdjustmentColumnNames))
+	adjustments, err = inTx(repo, mockPool).GetScheduleAdjustments(ctx, 2)
+	require.NoError(t, err)
+	assert.NotNil(t, adjustments)
+	assert.Empty(t, adjustments)
+
+	mockPool.ExpectQuery(regexp.QuoteMeta(getScheduleAdjustmentsQuery)).WithArgs(int64(3)).
+		WillReturnError(errors.New("connection reset"))
+	_, err = repo.GetScheduleAdjustments(ctx, 3)
+	assert.ErrorIs(t, err, apperrors.ErrDatabase)
+
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestLoanRepositoryAddScheduleAdjustment(t *testing.T) {
+	admin := "admin"
+	newPlan := func() *loan.AdjustmentPlan {
+		return &loan.AdjustmentPlan{
+			Adjustment: loan.ScheduleAdjustment{
+				LoanID: 1, Kind: loan.AdjustmentZeroInterest, StartsOn: time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC),
+				Weeks: 1, Reason: "promotion", AppliedBy: &admin, AppliedAt: testClock.Now(),
+			},
+			TotalLoanAmount: 320,
+		}
+	}
+
+	t.Run("records the adjustment and the new total", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+		plan := newPlan()
+		a := plan.Adjustment
+		mockPool.ExpectExec(regexp.QuoteMeta(updateLoanTotalQuery)).WithArgs(320.0, testClock.Now(), int64(1)).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mockPool.ExpectQuery(regexp.QuoteMeta(insertScheduleAdjustmentQuery)).
+			WithArgs(int64(1), loan.AdjustmentZeroInterest, a.StartsOn, 1, "promotion", &admin, testClock.Now()).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int64(5)))
+
+		require.NoError(t, inTx(repo, mockPool).AddScheduleAdjustment(ctx, plan))
+
+		assert.Equal(t, int64(5), plan.Adjustment.ID)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("fails when the loan is gone", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+		mockPool.ExpectExec(regexp.QuoteMeta(updateLoanTotalQuery)).WithArgs(320.0, testClock.Now(), int64(1)).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+		err := inTx(repo, mockPool).AddScheduleAdjustment(ctx, newPlan())
+
+		assert.ErrorIs(t, err, apperrors.ErrDatabase)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+}
+
+func TestLoanRepositoryRemoveScheduleAdjustment(t *testing.T) {
+	admin := "admin"
+	removedAt := testClock.Now()
+	newPlan := func() *loan.AdjustmentPlan {
+		return &loan.AdjustmentPlan{
+			Adjustment:      loan.ScheduleAdjustment{ID: 5, LoanID: 1, RemovedBy: &admin, RemovedAt: &removedAt},
+			TotalLoanAmount: 330,
+		}
+	}
+
+	t.Run("marks the adjustment removed and restores the total", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+		mockPool.ExpectExec(regexp.QuoteMeta(removeScheduleAdjustmentQuery)).WithArgs(int64(5), int64(1), &admin, &removedAt).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mockPool.ExpectExec(regexp.QuoteMeta(updateLoanTotalQuery)).WithArgs(330.0, testClock.Now(), int64(1)).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+		require.NoError(t, inTx(repo, mockPool).RemoveScheduleAdjustment(ctx, newPlan()))
+
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("reports an adjustment already removed", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+		mockPool.ExpectExec(regexp.QuoteMeta(removeScheduleAdjustmentQuery)).WithArgs(int64(5), int64(1), &admin, &removedAt).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+		err := inTx(repo, mockPool).RemoveScheduleAdjustment(ctx, newPlan())
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+}
@@ -0,0 +1,96 @@
+package postgres
+
+import (
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"fmt"
+)
+
+const (
+	scheduleForUpdateQuery = `
+        SELECT id, loan_id, week_number, due_date, due_amount, paid_amount, payment_date, status, created_at, updated_at
+        FROM loan_schedule
+        WHERE loan_id = $1
+        ORDER BY week_number ASC
+        FOR UPDATE`
+	insertScheduleEntryQuery = `
+        INSERT INTO loan_schedule (loan_id, week_number, due_date, due_amount, status, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $6)
+        RETURNING id`
+	rescheduleEntryQuery = `
+        UPDATE loan_schedule
+        SET due_date = $1, due_amount = $2, status = $3, updated_at = $4
+        WHERE id = $5 AND loan_id = $6`
+	deleteScheduleEntryQuery = `DELETE FROM loan_schedule WHERE id = $1 AND loan_id = $2`
+)
+
+func (t *loanTx) GetScheduleForUpdate(ctx context.Context, loanID int64) ([]loan.ScheduleEntry, error) {
+	rows, err := t.tx.Query(ctx, scheduleForUpdateQuery, loanID)
+	if err != nil {
+		t.r.logger.ErrorContext(ctx, "Failed to lock loan schedule", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	schedule := make([]loan.ScheduleEntry, 0)
+	for rows.Next() {
+		var entry loan.ScheduleEntry
+		err := rows.Scan(
+			&entry.ID, &entry.LoanID, &entry.WeekNumber, &entry.DueDate,
+			&entry.DueAmount, &entry.PaidAmount, &entry.PaymentDate,
+			&entry.Status, &entry.CreatedAt, &entry.UpdatedAt,
+		)
+		if err != nil {
+			t.r.logger.ErrorContext(ctx, "Failed to scan schedule row", "loan_id", loanID, "error", err)
+			return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+		}
+		schedule = append(schedule, entry)
+	}
+	if err := rows.Err(); err != nil {
+		t.r.logger.ErrorContext(ctx, "Error iterating schedule rows", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	return schedule, nil
+}
+
+func (t *loanTx) ApplyScheduleChanges(ctx context.Context, loanID int64, changes []loan.ScheduleChange) error {
+	now := t.r.clock.Now()
+	for _, change := range changes {
+		var affected int64
+		switch change.Kind {
+		case loan.ScheduleEntryAdded:
+			entry := change.After
+			if err := t.tx.QueryRow(ctx, insertScheduleEntryQuery, loanID, entry.WeekNumber, entry.DueDate, entry.DueAmount, entry.Status, now).Scan(&entry.ID); err != nil {
+				t.r.logger.ErrorContext(ctx, "Failed to insert schedule entry", "loan_id", loanID, "week", change.WeekNumber, "error", err)
+				return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+			}
+			entry.LoanID, entry.CreatedAt, entry.UpdatedAt = loanID, now, now
+			continue
+		case loan.ScheduleEntryUpdated:
+			entry := change.After
+			cmdTag, err := t.tx.Exec(ctx, rescheduleEntryQuery, entry.DueDate, entry.DueAmount, entry.Status, now, entry.ID, loanID)
+			if err != nil {
+				t.r.logger.ErrorContext(ctx, "Failed to reschedule entry", "loan_id", loanID, "entry_id", entry.ID, "error", err)
+				return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+			}
+			entry.UpdatedAt = now
+			affected = cmdTag.RowsAffected()
+		case loan.ScheduleEntryRemoved:
+			cmdTag, err := t.tx.Exec(ctx, deleteScheduleEntryQuery, change.Before.ID, loanID)
+			if err != nil {
+				t.r.logger.ErrorContext(ctx, "Failed to delete schedule entry", "loan_id", loanID, "entry_id", change.Before.ID, "error", err)
+				return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+			}
+			affected = cmdTag.RowsAffected()
+		default:
+			return fmt.Errorf("%w: unknown schedule change %q", apperrors.ErrInternalServer, change.Kind)
+		}
+		if affected != 1 {
+			t.r.logger.ErrorContext(ctx, "Schedule change affected no row", "loan_id", loanID, "week", change.WeekNumber, "kind", change.Kind)
+			return fmt.Errorf("%w: %s of week %d affected %d rows", apperrors.ErrDatabase, change.Kind, change.WeekNumber, affected)
+		}
+	}
+	t.r.logger.InfoContext(ctx, "Loan schedule rebuilt in DB", "loan_id", loanID, "changes", len(changes))
+	return nil
+}
@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/pkg/apperrors"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoanRepositoryGetScheduleForUpdate(t *testing.T) {
+	ctx, repo, mockPool := setupLoanRepo(t)
+	defer mockPool.Close()
+
+	due := time.Date(2025, 1, 13, 0, 0, 0, 0, time.UTC)
+	cols := []string{"id", "loan_id", "week_number", "due_date", "due_amount", "paid_amount", "payment_date", "status", "created_at", "updated_at"}
+	mockPool.ExpectQuery(regexp.QuoteMeta(scheduleForUpdateQuery)).WithArgs(int64(1)).
+		WillReturnRows(pgxmock.NewRows(cols).AddRow(int64(5), int64(1), 1, due, 110.0, 0.0, nil, loan.PaymentStatusPending, due, due))
+
+	schedule, err := inTx(repo, mockPool).GetScheduleForUpdate(ctx, 1)
+
+	require.NoError(t, err)
+	require.Len(t, schedule, 1)
+	assert.Equal(t, int64(5), schedule[0].ID)
+	assert.Equal(t, due, schedule[0].DueDate)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestLoanRepositoryApplyScheduleChanges(t *testing.T) {
+	due := time.Date(2025, 1, 27, 0, 0, 0, 0, time.UTC)
+
+	t.Run("inserts, updates and deletes weeks", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+		added := &loan.ScheduleEntry{WeekNumber: 3, DueDate: due, DueAmount: 110, Status: loan.PaymentStatusPending}
+		updated := &loan.ScheduleEntry{ID: 2, LoanID: 1, WeekNumber: 2, DueDate: due.AddDate(0, 0, -7), DueAmount: 110, Status: loan.PaymentStatusPending}
+		changes := []loan.ScheduleChange{
+			{Kind: loan.ScheduleEntryUpdated, WeekNumber: 2, After: updated},
+			{Kind: loan.ScheduleEntryAdded, WeekNumber: 3, After: added},
+			{Kind: loan.ScheduleEntryRemoved, WeekNumber: 4, Before: &loan.ScheduleEntry{ID: 9, LoanID: 1, WeekNumber: 4}},
+		}
+
+		mockPool.ExpectExec(regexp.QuoteMeta(rescheduleEntryQuery)).
+			WithArgs(updated.DueDate, 110.0, loan.PaymentStatusPending, testClock.Now(), int64(2), int64(1)).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mockPool.ExpectQuery(regexp.QuoteMeta(insertScheduleEntryQuery)).
+			WithArgs(int64(1), 3, due, 110.0, loan.PaymentStatusPending, testClock.Now()).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int64(12)))
+		mockPool.ExpectExec(regexp.QuoteMeta(deleteScheduleEntryQuery)).WithArgs(int64(9), int64(1)).
+			WillReturnResult(pgxmock.NewResult("DELETE", 1))
+
+		err := inTx(repo, mockPool).ApplyScheduleChanges(ctx, 1, changes)
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(12), added.ID)
+		assert.Equal(t, int64(1), added.LoanID)
+		assert.Equal(t, testClock.Now(), added.CreatedAt)
+		assert.Equal(t, testClock.Now(), updated.UpdatedAt)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("fails when a week is gone", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+		mockPool.ExpectExec(regexp.QuoteMeta(deleteScheduleEntryQuery)).WithArgs(int64(9), int64(1)).
+			WillReturnResult(pgxmock.NewResult("DELETE", 0))
+
+		err := inTx(repo, mockPool).ApplyScheduleChanges(ctx, 1, []loan.ScheduleChange{
+			{Kind: loan.ScheduleEntryRemoved, WeekNumber: 4, Before: &loan.ScheduleEntry{ID: 9, LoanID: 1}},
+		})
+
+		assert.ErrorIs(t, err, apperrors.ErrDatabase)
+	})
+
+	t.Run("wraps a failed insert", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+		mockPool.ExpectQuery(regexp.QuoteMeta(insertScheduleEntryQuery)).WillReturnError(errors.New("connection reset"))
+
+		err := inTx(repo, mockPool).ApplyScheduleChanges(ctx, 1, []loan.ScheduleChange{
+			{Kind: loan.ScheduleEntryAdded, WeekNumber: 3, After: &loan.ScheduleEntry{WeekNumber: 3, DueDate: due}},
+		})
+
+		assert.ErrorIs(t, err, apperrors.ErrDatabase)
+	})
+}
@@ -0,0 +1,179 @@
+package postgres
+
+import (
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/infrastructure/monitoring"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+var _ loan.SnapshotRepository = (*LoanRepository)(nil)
+
+// writeSnapshotsQuery computes every loan's outstanding amount and days past
+// due as of $1 in one statement. DPD counts from the oldest unpaid
+// installment that was due before the snapshot date. $2 is the write time.
+const writeSnapshotsQuery = `
+        INSERT INTO loan_status_snapshots (snapshot_date, loan_id, status, outstanding, dpd, created_at)
+        SELECT $1::date, l.id, l.status,
+               GREATEST(COALESCE(SUM(s.due_amount - s.paid_amount) FILTER (WHERE s.status != 'PAID'), 0), 0),
+               COALESCE($1::date - MIN(s.due_date) FILTER (WHERE s.status != 'PAID' AND s.due_date < $1::date), 0),
+               $2
+        FROM loans l
+        LEFT JOIN loan_schedule s ON s.loan_id = l.id
+        WHERE l.created_at < $1::date + 1
+        GROUP BY l.id, l.status
+        ON CONFLICT (loan_id, snapshot_date) DO UPDATE
+        SET status = EXCLUDED.status, outstanding = EXCLUDED.outstanding, dpd = EXCLUDED.dpd, created_at = EXCLUDED.created_at`
+
+const getSnapshotsQuery = `
+        SELECT loan_id, snapshot_date, status, outstanding, dpd
+        FROM loan_status_snapshots
+        WHERE loan_id = $1 AND snapshot_date BETWEEN $2 AND $3
+        ORDER BY snapshot_date ASC`
+
+// The portfolio queries read archived loans' snapshots too, so a report for
+// a past date does not change when its loans are archived. A loan's
+// snapshots are either all live or all archived.
+const latestSnapshotDateQuery = `
+        SELECT MAX(snapshot_date) FROM (
+            SELECT MAX(snapshot_date) AS snapshot_date FROM loan_status_snapshots WHERE snapshot_date <= $1
+            UNION ALL
+            SELECT MAX(snapshot_date) FROM loan_status_snapshots_archive WHERE snapshot_date <= $1
+        ) latest`
+
+const getPortfolioRowsQuery = `
+        SELECT status, dpd, COUNT(*), COALESCE(SUM(outstanding), 0)
+        FROM (
+            SELECT status, dpd, outstanding FROM loan_status_snapshots WHERE snapshot_date = $1
+            UNION ALL
+            SELECT status, dpd, outstanding FROM loan_status_snapshots_archive WHERE snapshot_date = $1
+        ) snapshots
+        GROUP BY status, dpd
+        ORDER BY status, dpd`
+
+const streamSnapshotsQuery = `
+        SELECT loan_id, snapshot_date, status, outstanding, dpd
+        FROM loan_status_snapshots
+        WHERE snapshot_date = $1 AND loan_id > $2
+        UNION ALL
+        SELECT loan_id, snapshot_date, status, outstanding, dpd
+        FROM loan_status_snapshots_archive
+        WHERE snapshot_date = $1 AND loan_id > $2
+        ORDER BY loan_id`
+
+func (r *LoanRepository) WriteDailySnapshots(ctx context.Context, date time.Time) (int64, error) {
+	start := time.Now()
+	logCtx := r.logger.With(slog.String("operation", "WriteDailySnapshots"), slog.String("date", date.Format(time.DateOnly)))
+
+	tag, err := r.db.Exec(ctx, writeSnapshotsQuery, date, r.clock.Now())
+	if err != nil {
+		monitoring.RecordDBQuery("WriteDailySnapshots", "error", time.Since(start))
+		logCtx.ErrorContext(ctx, "Failed to write loan snapshots", slog.Any("error", err))
+		return 0, fmt.Errorf("%w: failed to write loan snapshots: %w", apperrors.ErrDatabase, err)
+	}
+	monitoring.RecordDBQuery("WriteDailySnapshots", "success", time.Since(start))
+	return tag.RowsAffected(), nil
+}
+
+func (r *LoanRepository) GetSnapshots(ctx context.Context, loanID int64, from, to time.Time) ([]loan.Snapshot, error) {
+	start := time.Now()
+	rows, err := r.db.Query(ctx, getSnapshotsQuery, loanID, from, to)
+	if err != nil {
+		monitoring.RecordDBQuery("GetSnapshots", "error", time.Since(start))
+		r.logger.ErrorContext(ctx, "Failed to query loan snapshots", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	snapshots := make([]loan.Snapshot, 0)
+	for rows.Next() {
+		var s loan.Snapshot
+		if err := rows.Scan(&s.LoanID, &s.Date, &s.Status, &s.Outstanding, &s.DPD); err != nil {
+			r.logger.ErrorContext(ctx, "Failed to scan loan snapshot row", "loan_id", loanID, "error", err)
+			return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+		}
+		snapshots = append(snapshots, s)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.ErrorContext(ctx, "Error iterating loan snapshot rows", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+
+	monitoring.RecordDBQuery("GetSnapshots", "success", time.Since(start))
+	return snapshots, nil
+}
+
+func (r *LoanRepository) LatestSnapshotDate(ctx context.Context, onOrBefore time.Time) (time.Time, bool, error) {
+	var latest *time.Time
+	if err := r.db.QueryRow(ctx, latestSnapshotDateQuery, onOrBefore).Scan(&latest); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to find latest snapshot date", "error", err)
+		return time.Time{}, false, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	if latest == nil {
+		return time.Time{}, false, nil
+	}
+	return *latest, true, nil
+}
+
+func (r *LoanRepository) GetPortfolioRows(ctx context.Context, date time.Time) ([]loan.PortfolioRow, error) {
+	start := time.Now()
+	rows, err := r.db.Query(ctx, getPortfolioRowsQuery, date)
+	if err != nil {
+		monitoring.RecordDBQuery("GetPortfolioRows", "error", time.Since(start))
+		r.logger.ErrorContext(ctx, "Failed to query portfolio snapshot", "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	result := make([]loan.PortfolioRow, 0)
+	for rows.Next() {
+		var row loan.PortfolioRow
+		if err := rows.Scan(&row.Status, &row.DPD, &row.Loans, &row.Outstanding); err != nil {
+			r.logger.ErrorContext(ctx, "Failed to scan portfolio row", "error", err)
+			return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+		}
+		result = append(result, row)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.ErrorContext(ctx, "Error iterating portfolio rows", "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+
+	monitoring.RecordDBQuery("GetPortfolioRows", "success", time.Since(start))
+	return result, nil
+}
+
+func (r *LoanRepository) StreamSnapshots(ctx context.Context, date time.Time, afterLoanID int64, fn func(loan.Snapshot) error) error {
+	start := time.Now()
+	rows, err := r.db.Query(ctx, streamSnapshotsQuery, date, afterLoanID)
+	if err != nil {
+		monitoring.RecordDBQuery("StreamSnapshots", "error", time.Since(start))
+		r.logger.ErrorContext(ctx, "Failed to query snapshots for streaming", "date", date.Format(time.DateOnly), "error", err)
+		return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s loan.Snapshot
+		if err := rows.Scan(&s.LoanID, &s.Date, &s.Status, &s.Outstanding, &s.DPD); err != nil {
+			monitoring.RecordDBQuery("StreamSnapshots", "error", time.Since(start))
+			r.logger.ErrorContext(ctx, "Failed to scan streamed snapshot row", "date", date.Format(time.DateOnly), "error", err)
+			return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+		}
+		if err := fn(s); err != nil {
+			monitoring.RecordDBQuery("StreamSnapshots", "aborted", time.Since(start))
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		monitoring.RecordDBQuery("StreamSnapshots", "error", time.Since(start))
+		r.logger.ErrorContext(ctx, "Error iterating streamed snapshot rows", "date", date.Format(time.DateOnly), "error", err)
+		return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+
+	monitoring.RecordDBQuery("StreamSnapshots", "success", time.Since(start))
+	return nil
+}
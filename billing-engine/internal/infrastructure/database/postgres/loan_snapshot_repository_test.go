@@ -0,0 +1,140 @@
+package postgres
+
+import (
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/pkg/apperrors"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var snapshotDate = time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)
+
+func TestWriteDailySnapshots(t *testing.T) {
+	ctx, repo, mockPool := setupLoanRepo(t)
+	defer mockPool.Close()
+
+	mockPool.ExpectExec(regexp.QuoteMeta(writeSnapshotsQuery)).
+		WithArgs(snapshotDate, testClock.Now()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 3))
+
+	written, err := repo.WriteDailySnapshots(ctx, snapshotDate)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), written)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestWriteDailySnapshotsError(t *testing.T) {
+	ctx, repo, mockPool := setupLoanRepo(t)
+	defer mockPool.Close()
+
+	mockPool.ExpectExec(regexp.QuoteMeta(writeSnapshotsQuery)).
+		WithArgs(snapshotDate, testClock.Now()).
+		WillReturnError(errors.New("connection reset"))
+
+	_, err := repo.WriteDailySnapshots(ctx, snapshotDate)
+
+	assert.ErrorIs(t, err, apperrors.ErrDatabase)
+}
+
+func TestGetSnapshots(t *testing.T) {
+	ctx, repo, mockPool := setupLoanRepo(t)
+	defer mockPool.Close()
+
+	from := snapshotDate.AddDate(0, 0, -1)
+	mockPool.ExpectQuery(regexp.QuoteMeta(getSnapshotsQuery)).
+		WithArgs(int64(7), from, snapshotDate).
+		WillReturnRows(pgxmock.NewRows([]string{"loan_id", "snapshot_date", "status", "outstanding", "dpd"}).
+			AddRow(int64(7), from, loan.StatusActive, 1000.0, 0).
+			AddRow(int64(7), snapshotDate, loan.StatusDelinquent, 1000.0, 14))
+
+	snapshots, err := repo.GetSnapshots(ctx, 7, from, snapshotDate)
+
+	require.NoError(t, err)
+	require.Len(t, snapshots, 2)
+	assert.Equal(t, loan.StatusDelinquent, snapshots[1].Status)
+	assert.Equal(t, 14, snapshots[1].DPD)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestLatestSnapshotDate(t *testing.T) {
+	t.Run("found", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+
+		latest := snapshotDate.AddDate(0, 0, -2)
+		mockPool.ExpectQuery(regexp.QuoteMeta(latestSnapshotDateQuery)).
+			WithArgs(snapshotDate).
+			WillReturnRows(pgxmock.NewRows([]string{"max"}).AddRow(&latest))
+
+		got, ok, err := repo.LatestSnapshotDate(ctx, snapshotDate)
+
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, latest, got)
+	})
+
+	t.Run("none", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+
+		mockPool.ExpectQuery(regexp.QuoteMeta(latestSnapshotDateQuery)).
+			WithArgs(snapshotDate).
+			WillReturnRows(pgxmock.NewRows([]string{"max"}).AddRow(nil))
+
+		_, ok, err := repo.LatestSnapshotDate(ctx, snapshotDate)
+
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+}
+
+func TestGetPortfolioRows(t *testing.T) {
+	ctx, repo, mockPool := setupLoanRepo(t)
+	defer mockPool.Close()
+
+	mockPool.ExpectQuery(regexp.QuoteMeta(getPortfolioRowsQuery)).
+		WithArgs(snapshotDate).
+		WillReturnRows(pgxmock.NewRows([]string{"status", "dpd", "count", "sum"}).
+			AddRow(loan.StatusActive, 0, 4, 4000.0).
+			AddRow(loan.StatusDelinquent, 21, 1, 750.0))
+
+	rows, err := repo.GetPortfolioRows(ctx, snapshotDate)
+
+	require.NoError(t, err)
+	assert.Equal(t, []loan.PortfolioRow{
+		{Status: loan.StatusActive, DPD: 0, Loans: 4, Outstanding: 4000},
+		{Status: loan.StatusDelinquent, DPD: 21, Loans: 1, Outstanding: 750},
+	}, rows)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestStreamSnapshots(t *testing.T) {
+	ctx, repo, mockPool := setupLoanRepo(t)
+	defer mockPool.Close()
+
+	mockPool.ExpectQuery(regexp.QuoteMeta(streamSnapshotsQuery)).
+		WithArgs(snapshotDate, int64(3)).
+		WillReturnRows(pgxmock.NewRows([]string{"loan_id", "snapshot_date", "status", "outstanding", "dpd"}).
+			AddRow(int64(4), snapshotDate, loan.StatusActive, 400.0, 0).
+			AddRow(int64(5), snapshotDate, loan.StatusDelinquent, 750.0, 21))
+
+	var got []loan.Snapshot
+	err := repo.StreamSnapshots(ctx, snapshotDate, 3, func(s loan.Snapshot) error {
+		got = append(got, s)
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []loan.Snapshot{
+		{LoanID: 4, Date: snapshotDate, Status: loan.StatusActive, Outstanding: 400},
+		{LoanID: 5, Date: snapshotDate, Status: loan.StatusDelinquent, Outstanding: 750, DPD: 21},
+	}, got)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
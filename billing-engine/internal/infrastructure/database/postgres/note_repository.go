@@ -0,0 +1,243 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"billing-engine/internal/domain/note"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type NoteRepository struct {
+	db     DBPool
+	clock  clock.Clock
+	logger *slog.Logger
+}
+
+var _ note.Repository = (*NoteRepository)(nil)
+
+// NewNoteRepository builds the note repository; clk stamps created_at and
+// nil means the wall clock.
+func NewNoteRepository(db DBPool, clk clock.Clock, logger *slog.Logger) *NoteRepository {
+	if db == nil {
+		panic("DBPool cannot be nil for NoteRepository")
+	}
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+		logger.Warn("Warning: No logger provided to NewNoteRepository, using default stderr handler")
+	}
+	return &NoteRepository{
+		db:     db,
+		clock:  clock.OrSystem(clk),
+		logger: logger.With("component", "NoteRepository"),
+	}
+}
+
+// subjectColumns returns the foreign key column for the subject and its
+// parent table. Both come from a fixed set, so they are safe to splice into
+// SQL.
+func subjectColumns(subject note.Subject) (column, table string, err error) {
+	switch subject.Type {
+	case note.SubjectLoan:
+		return "loan_id", "loans", nil
+	case note.SubjectCustomer:
+		return "customer_id", "customers", nil
+	}
+	return "", "", fmt.Errorf("%w: unknown subject type %q", apperrors.ErrInvalidArgument, subject.Type)
+}
+
+// noteWriteError maps a foreign key violation, which means the loan or
+// customer does not exist, to not found.
+func (r *NoteRepository) noteWriteError(err error, subject note.Subject, what string) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		return fmt.Errorf("%w: %s", apperrors.ErrNotFound, subject)
+	}
+	return fmt.Errorf("%w: failed to insert %s: %w", apperrors.ErrDatabase, what, err)
+}
+
+func (r *NoteRepository) CreateNote(ctx context.Context, n *note.Note) error {
+	column, _, err := subjectColumns(n.Subject)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`
+        INSERT INTO notes (%s, body, author, created_at)
+        VALUES ($1, $2, NULLIF($3, ''), $4)
+        RETURNING id, created_at`, column)
+
+	if err := r.db.QueryRow(ctx, query, n.Subject.ID, n.Body, n.Author, r.clock.Now()).Scan(&n.ID, &n.CreatedAt); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to insert note", slog.String("subject", n.Subject.String()), slog.Any("error", err))
+		return r.noteWriteError(err, n.Subject, "note")
+	}
+	return nil
+}
+
+func (r *NoteRepository) ListNotes(ctx context.Context, subject note.Subject) ([]note.Note, error) {
+	column, _, err := subjectColumns(subject)
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`
+        SELECT id, body, COALESCE(author, ''), created_at
+        FROM notes
+        WHERE %s = $1
+        ORDER BY created_at DESC, id DESC`, column)
+
+	rows, err := r.db.Query(ctx, query, subject.ID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query notes", slog.String("subject", subject.String()), slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to list notes: %w", apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	notes := []note.Note{}
+	for rows.Next() {
+		n := note.Note{Subject: subject}
+		if err := rows.Scan(&n.ID, &n.Body, &n.Author, &n.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%w: failed to scan note: %w", apperrors.ErrDatabase, err)
+		}
+		notes = append(notes, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: failed to iterate notes: %w", apperrors.ErrDatabase, err)
+	}
+	return notes, nil
+}
+
+func (r *NoteRepository) DeleteNote(ctx context.Context, subject note.Subject, noteID int64) error {
+	column, _, err := subjectColumns(subject)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`DELETE FROM notes WHERE id = $1 AND %s = $2`, column)
+	tag, err := r.db.Exec(ctx, query, noteID, subject.ID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to delete note", slog.Int64("noteID", noteID), slog.Any("error", err))
+		return fmt.Errorf("%w: failed to delete note: %w", apperrors.ErrDatabase, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: note %d of %s", apperrors.ErrNotFound, noteID, subject)
+	}
+	return nil
+}
+
+func (r *NoteRepository) CreateAttachment(ctx context.Context, a *note.Attachment) error {
+	column, _, err := subjectColumns(a.Subject)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`
+        INSERT INTO attachments (%s, file_name, content_type, size_bytes, storage_key, uploaded_by, created_at)
+        VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7)
+        RETURNING id, created_at`, column)
+
+	err = r.db.QueryRow(ctx, query, a.Subject.ID, a.FileName, a.ContentType, a.SizeBytes, a.StorageKey, a.UploadedBy, r.clock.Now()).
+		Scan(&a.ID, &a.CreatedAt)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to insert attachment", slog.String("subject", a.Subject.String()), slog.Any("error", err))
+		return r.noteWriteError(err, a.Subject, "attachment")
+	}
+	return nil
+}
+
+func (r *NoteRepository) ListAttachments(ctx context.Context, subject note.Subject) ([]note.Attachment, error) {
+	column, _, err := subjectColumns(subject)
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`
+        SELECT id, file_name, content_type, size_bytes, storage_key, COALESCE(uploaded_by, ''), created_at
+        FROM attachments
+        WHERE %s = $1
+        ORDER BY created_at DESC, id DESC`, column)
+
+	rows, err := r.db.Query(ctx, query, subject.ID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query attachments", slog.String("subject", subject.String()), slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to list attachments: %w", apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	attachments := []note.Attachment{}
+	for rows.Next() {
+		a := note.Attachment{Subject: subject}
+		if err := rows.Scan(&a.ID, &a.FileName, &a.ContentType, &a.SizeBytes, &a.StorageKey, &a.UploadedBy, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%w: failed to scan attachment: %w", apperrors.ErrDatabase, err)
+		}
+		attachments = append(attachments, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: failed to iterate attachments: %w", apperrors.ErrDatabase, err)
+	}
+	return attachments, nil
+}
+
+func (r *NoteRepository) GetAttachment(ctx context.Context, subject note.Subject, attachmentID int64) (*note.Attachment, error) {
+	column, _, err := subjectColumns(subject)
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`
+        SELECT id, file_name, content_type, size_bytes, storage_key, COALESCE(uploaded_by, ''), created_at
+        FROM attachments
+        WHERE id = $1 AND %s = $2`, column)
+
+	a := note.Attachment{Subject: subject}
+	err = r.db.QueryRow(ctx, query, attachmentID, subject.ID).
+		Scan(&a.ID, &a.FileName, &a.ContentType, &a.SizeBytes, &a.StorageKey, &a.UploadedBy, &a.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%w: attachment %d of %s", apperrors.ErrNotFound, attachmentID, subject)
+		}
+		r.logger.ErrorContext(ctx, "Failed to get attachment", slog.Int64("attachmentID", attachmentID), slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to get attachment: %w", apperrors.ErrDatabase, err)
+	}
+	return &a, nil
+}
+
+func (r *NoteRepository) DeleteAttachment(ctx context.Context, subject note.Subject, attachmentID int64) error {
+	column, _, err := subjectColumns(subject)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`DELETE FROM attachments WHERE id = $1 AND %s = $2`, column)
+	tag, err := r.db.Exec(ctx, query, attachmentID, subject.ID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to delete attachment", slog.Int64("attachmentID", attachmentID), slog.Any("error", err))
+		return fmt.Errorf("%w: failed to delete attachment: %w", apperrors.ErrDatabase, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: attachment %d of %s", apperrors.ErrNotFound, attachmentID, subject)
+	}
+	return nil
+}
+
+func (r *NoteRepository) SubjectExists(ctx context.Context, subject note.Subject) (bool, error) {
+	_, table, err := subjectColumns(subject)
+	if err != nil {
+		return false, err
+	}
+
+	var exists bool
+	query := fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM %s WHERE id = $1)`, table)
+	if err := r.db.QueryRow(ctx, query, subject.ID).Scan(&exists); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to check subject", slog.String("subject", subject.String()), slog.Any("error", err))
+		return false, fmt.Errorf("%w: failed to check %s: %w", apperrors.ErrDatabase, subject.Type, err)
+	}
+	return exists, nil
+}
@@ -0,0 +1,138 @@
+package postgres
+
+import (
+	"billing-engine/internal/domain/note"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var noteLoanSubject = note.Subject{Type: note.SubjectLoan, ID: 42}
+
+func setupNoteRepo(t *testing.T) (context.Context, *NoteRepository, pgxmock.PgxPoolIface) {
+	t.Helper()
+	mockPool, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("failed to open a stub database connection: %v", err)
+	}
+	return context.Background(), NewNoteRepository(mockPool, testClock, logger), mockPool
+}
+
+func TestNoteRepositoryCreateNote(t *testing.T) {
+	ctx, repo, mockPool := setupNoteRepo(t)
+	defer mockPool.Close()
+
+	now := time.Now()
+	mockPool.ExpectQuery(`INSERT INTO notes \(loan_id, body, author, created_at\)`).
+		WithArgs(int64(42), "Promised to pay Friday", "ops", testClock.Now()).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "created_at"}).AddRow(int64(7), now))
+
+	n := &note.Note{Subject: noteLoanSubject, Body: "Promised to pay Friday", Author: "ops"}
+	err := repo.CreateNote(ctx, n)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), n.ID)
+	assert.Equal(t, now, n.CreatedAt)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestNoteRepositoryCreateNoteUnknownSubject(t *testing.T) {
+	ctx, repo, mockPool := setupNoteRepo(t)
+	defer mockPool.Close()
+
+	mockPool.ExpectQuery(`INSERT INTO notes \(customer_id, body, author, created_at\)`).
+		WithArgs(int64(9), "Moved house", "", testClock.Now()).
+		WillReturnError(&pgconn.PgError{Code: "23503", ConstraintName: "notes_customer_id_fkey"})
+
+	err := repo.CreateNote(ctx, &note.Note{Subject: note.Subject{Type: note.SubjectCustomer, ID: 9}, Body: "Moved house"})
+
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestNoteRepositoryListNotes(t *testing.T) {
+	ctx, repo, mockPool := setupNoteRepo(t)
+	defer mockPool.Close()
+
+	now := time.Now()
+	mockPool.ExpectQuery(`SELECT id, body, COALESCE\(author, ''\), created_at FROM notes WHERE loan_id = \$1`).
+		WithArgs(int64(42)).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "body", "author", "created_at"}).
+			AddRow(int64(2), "second", "ops", now).
+			AddRow(int64(1), "first", "", now.Add(-time.Hour)))
+
+	notes, err := repo.ListNotes(ctx, noteLoanSubject)
+
+	require.NoError(t, err)
+	require.Len(t, notes, 2)
+	assert.Equal(t, "second", notes[0].Body)
+	assert.Equal(t, noteLoanSubject, notes[1].Subject)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestNoteRepositoryDeleteNoteNotFound(t *testing.T) {
+	ctx, repo, mockPool := setupNoteRepo(t)
+	defer mockPool.Close()
+
+	mockPool.ExpectExec(`DELETE FROM notes WHERE id = \$1 AND loan_id = \$2`).
+		WithArgs(int64(3), int64(42)).
+		WillReturnResult(pgxmock.NewResult("DELETE", 0))
+
+	err := repo.DeleteNote(ctx, noteLoanSubject, 3)
+
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestNoteRepositoryCreateAttachment(t *testing.T) {
+	ctx, repo, mockPool := setupNoteRepo(t)
+	defer mockPool.Close()
+
+	now := time.Now()
+	mockPool.ExpectQuery(`INSERT INTO attachments \(loan_id, file_name, content_type, size_bytes, storage_key, uploaded_by, created_at\)`).
+		WithArgs(int64(42), "contract.pdf", "application/pdf", int64(2048), "loans/42/key", "ops", testClock.Now()).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "created_at"}).AddRow(int64(11), now))
+
+	a := &note.Attachment{Subject: noteLoanSubject, FileName: "contract.pdf", ContentType: "application/pdf", SizeBytes: 2048, StorageKey: "loans/42/key", UploadedBy: "ops"}
+	err := repo.CreateAttachment(ctx, a)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(11), a.ID)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestNoteRepositoryGetAttachmentNotFound(t *testing.T) {
+	ctx, repo, mockPool := setupNoteRepo(t)
+	defer mockPool.Close()
+
+	mockPool.ExpectQuery(`FROM attachments WHERE id = \$1 AND customer_id = \$2`).
+		WithArgs(int64(11), int64(9)).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "file_name", "content_type", "size_bytes", "storage_key", "uploaded_by", "created_at"}))
+
+	a, err := repo.GetAttachment(ctx, note.Subject{Type: note.SubjectCustomer, ID: 9}, 11)
+
+	assert.Nil(t, a)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestNoteRepositorySubjectExists(t *testing.T) {
+	ctx, repo, mockPool := setupNoteRepo(t)
+	defer mockPool.Close()
+
+	mockPool.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM customers WHERE id = \$1\)`).
+		WithArgs(int64(9)).
+		WillReturnRows(pgxmock.NewRows([]string{"exists"}).AddRow(true))
+
+	exists, err := repo.SubjectExists(ctx, note.Subject{Type: note.SubjectCustomer, ID: 9})
+
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
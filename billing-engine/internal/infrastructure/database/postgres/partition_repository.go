@@ -0,0 +1,140 @@
+package postgres
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// schedulePartitionLoans is the number of loan IDs one loan_schedule
+// partition covers. Migration 020 uses the same width.
+const schedulePartitionLoans = 100000
+
+const (
+	lastLoanIDQuery     = `SELECT last_value FROM loans_id_seq`
+	listPartitionsQuery = `
+        SELECT c.relname
+        FROM pg_inherits i
+        JOIN pg_class c ON c.oid = i.inhrelid
+        WHERE i.inhparent IN ('loan_schedule'::regclass, 'payments'::regclass)`
+)
+
+// rangePartition is a partition of parent holding the keys from from up to,
+// but not including, to. The bounds are SQL literals.
+type rangePartition struct {
+	parent, name, from, to string
+}
+
+func (p rangePartition) createQuery() string {
+	return fmt.Sprintf(`CREATE TABLE %s PARTITION OF %s FOR VALUES FROM (%s) TO (%s)`,
+		pgx.Identifier{p.name}.Sanitize(), p.parent, p.from, p.to)
+}
+
+// schedulePartitions returns the loan_schedule partition the loan after
+// lastLoanID goes to and the one after it.
+func schedulePartitions(lastLoanID int64) []rangePartition {
+	first := (lastLoanID + 1) / schedulePartitionLoans
+	partitions := make([]rangePartition, 0, 2)
+	for i := first; i <= first+1; i++ {
+		lower := i * schedulePartitionLoans
+		partitions = append(partitions, rangePartition{
+			parent: "loan_schedule",
+			name:   fmt.Sprintf("loan_schedule_p%d", i),
+			from:   strconv.FormatInt(lower, 10),
+			to:     strconv.FormatInt(lower+schedulePartitionLoans, 10),
+		})
+	}
+	return partitions
+}
+
+// paymentPartitions returns the monthly payments partitions, in UTC, from
+// the month of now through monthsAhead months later.
+func paymentPartitions(now time.Time, monthsAhead int) []rangePartition {
+	year, month, _ := now.UTC().Date()
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	partitions := make([]rangePartition, 0, monthsAhead+1)
+	for i := 0; i <= monthsAhead; i++ {
+		from := first.AddDate(0, i, 0)
+		partitions = append(partitions, rangePartition{
+			parent: "payments",
+			name:   fmt.Sprintf("payments_y%04dm%02d", from.Year(), from.Month()),
+			from:   timestampBound(from),
+			to:     timestampBound(from.AddDate(0, 1, 0)),
+		})
+	}
+	return partitions
+}
+
+func timestampBound(t time.Time) string {
+	return "'" + t.UTC().Format(time.DateTime) + "+00'"
+}
+
+// EnsurePartitions creates the missing partitions one statement at a time,
+// so that one that cannot be created does not hold up the others. A range
+// an existing partition already covers, such as the legacy partitions of
+// migration 020, is skipped. A range with rows in the default partition is
+// ErrConflict: those rows have to be moved out by hand before it can be
+// created.
+func (r *LoanRepository) EnsurePartitions(ctx context.Context, now time.Time, monthsAhead int) ([]string, error) {
+	var lastLoanID int64
+	if err := r.db.QueryRow(ctx, lastLoanIDQuery).Scan(&lastLoanID); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to read the last loan ID", "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	existing, err := r.listPartitions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	created := []string{}
+	var errs []error
+	for _, p := range append(schedulePartitions(lastLoanID), paymentPartitions(now, monthsAhead)...) {
+		if existing[p.name] {
+			continue
+		}
+		_, err := r.db.Exec(ctx, p.createQuery())
+		var pgErr *pgconn.PgError
+		switch {
+		case err == nil:
+			r.logger.InfoContext(ctx, "Partition created", "partition", p.name, "from", p.from, "to", p.to)
+			created = append(created, p.name)
+		case errors.As(err, &pgErr) && pgErr.Code == "42P17":
+			// would overlap an existing partition
+		case errors.As(err, &pgErr) && pgErr.Code == "23514":
+			r.logger.ErrorContext(ctx, "Default partition holds rows of a missing partition", "partition", p.name, "error", err)
+			errs = append(errs, fmt.Errorf("%w: the default partition of %s holds rows for %s", apperrors.ErrConflict, p.parent, p.name))
+		default:
+			r.logger.ErrorContext(ctx, "Failed to create partition", "partition", p.name, "error", err)
+			errs = append(errs, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err))
+		}
+	}
+	return created, errors.Join(errs...)
+}
+
+func (r *LoanRepository) listPartitions(ctx context.Context) (map[string]bool, error) {
+	rows, err := r.db.Query(ctx, listPartitionsQuery)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to list partitions", "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	existing := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+		}
+		existing[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	return existing, nil
+}
@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedulePartitions(t *testing.T) {
+	partitions := schedulePartitions(199999)
+
+	require.Len(t, partitions, 2)
+	assert.Equal(t, rangePartition{parent: "loan_schedule", name: "loan_schedule_p2", from: "200000", to: "300000"}, partitions[0])
+	assert.Equal(t, "loan_schedule_p3", partitions[1].name)
+	assert.Equal(t, `CREATE TABLE "loan_schedule_p2" PARTITION OF loan_schedule FOR VALUES FROM (200000) TO (300000)`, partitions[0].createQuery())
+}
+
+func TestPaymentPartitions(t *testing.T) {
+	// 03:00 on 1 December in Jakarta is still 30 November in UTC, and the
+	// partitions are months in UTC.
+	jakarta := time.FixedZone("WIB", 7*3600)
+	partitions := paymentPartitions(time.Date(2025, 12, 1, 3, 0, 0, 0, jakarta), 2)
+
+	require.Len(t, partitions, 3)
+	assert.Equal(t, rangePartition{parent: "payments", name: "payments_y2025m11", from: "'2025-11-01 00:00:00+00'", to: "'2025-12-01 00:00:00+00'"}, partitions[0])
+	assert.Equal(t, "payments_y2025m12", partitions[1].name)
+	assert.Equal(t, "payments_y2026m01", partitions[2].name)
+}
+
+func TestLoanRepositoryEnsurePartitions(t *testing.T) {
+	now := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+
+	t.Run("creates the missing partitions", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+		mockPool.ExpectQuery(regexp.QuoteMeta(lastLoanIDQuery)).WillReturnRows(pgxmock.NewRows([]string{"last_value"}).AddRow(int64(150000)))
+		mockPool.ExpectQuery(regexp.QuoteMeta(listPartitionsQuery)).
+			WillReturnRows(pgxmock.NewRows([]string{"relname"}).AddRow("loan_schedule_p1").AddRow("payments_y2025m04"))
+		mockPool.ExpectExec(regexp.QuoteMeta(schedulePartitions(150000)[1].createQuery())).
+			WillReturnResult(pgxmock.NewResult("CREATE TABLE", 0))
+		// March is covered by the legacy partition.
+		mockPool.ExpectExec(regexp.QuoteMeta(paymentPartitions(now, 1)[0].createQuery())).
+			WillReturnError(&pgconn.PgError{Code: "42P17", Message: `partition "payments_y2025m03" would overlap partition "payments_legacy"`})
+
+		created, err := repo.EnsurePartitions(ctx, now, 1)
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"loan_schedule_p2"}, created)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("reports rows stuck in the default partition and carries on", func(t *testing.T) {
+		ctx, repo, mockPool := setupLoanRepo(t)
+		defer mockPool.Close()
+		mockPool.ExpectQuery(regexp.QuoteMeta(lastLoanIDQuery)).WillReturnRows(pgxmock.NewRows([]string{"last_value"}).AddRow(int64(10)))
+		mockPool.ExpectQuery(regexp.QuoteMeta(listPartitionsQuery)).
+			WillReturnRows(pgxmock.NewRows([]string{"relname"}).AddRow("loan_schedule_p0").AddRow("loan_schedule_p1"))
+		mockPool.ExpectExec(regexp.QuoteMeta(paymentPartitions(now, 0)[0].createQuery())).
+			WillReturnError(&pgconn.PgError{Code: "23514", Message: `updated partition constraint for default partition "payments_default" would be violated by some row`})
+
+		created, err := repo.EnsurePartitions(ctx, now, 0)
+
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+		assert.ErrorContains(t, err, "payments_y2025m03")
+		assert.Empty(t, created)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+}
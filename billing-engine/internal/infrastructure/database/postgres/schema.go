@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/migrations"
+	"context"
+	"fmt"
+)
+
+const (
+	relationExistsQuery = `SELECT to_regclass($1) IS NOT NULL`
+	columnExistsQuery   = `
+        SELECT EXISTS (
+            SELECT 1 FROM information_schema.columns
+            WHERE table_schema = current_schema() AND table_name = $1 AND column_name = $2
+        )`
+	policyExistsQuery = `
+        SELECT EXISTS (
+            SELECT 1 FROM pg_policies
+            WHERE schemaname = current_schema() AND tablename = $1 AND policyname = $2
+        )`
+)
+
+// SchemaStatus says how far the database's schema has got. Current is the
+// newest migration whose objects all exist, empty when none does. Pending
+// lists the newer ones, oldest first, and Missing their objects that were
+// not found.
+type SchemaStatus struct {
+	Current string
+	Pending []string
+	Missing []migrations.Object
+}
+
+// CheckSchema compares the database with ms, which are in file name order.
+// There is no table recording applied migrations, so a migration counts as
+// applied when the tables, indexes, columns and policies it adds exist. It looks from
+// the newest migration back and stops at the first applied one, because
+// later migrations may rename what earlier ones created.
+func CheckSchema(ctx context.Context, db queryRower, ms []migrations.Migration) (SchemaStatus, error) {
+	var status SchemaStatus
+	current := -1
+	for i := len(ms) - 1; i >= 0 && current < 0; i-- {
+		objects := ms[i].Objects()
+		if len(objects) == 0 {
+			continue
+		}
+		var missing []migrations.Object
+		for _, object := range objects {
+			var exists bool
+			var err error
+			switch {
+			case object.Column != "":
+				err = db.QueryRow(ctx, columnExistsQuery, object.Relation, object.Column).Scan(&exists)
+			case object.Policy != "":
+				err = db.QueryRow(ctx, policyExistsQuery, object.Relation, object.Policy).Scan(&exists)
+			default:
+				err = db.QueryRow(ctx, relationExistsQuery, object.Relation).Scan(&exists)
+			}
+			if err != nil {
+				return SchemaStatus{}, fmt.Errorf("%w: failed to look up %s: %w", apperrors.ErrDatabase, object, err)
+			}
+			if !exists {
+				missing = append(missing, object)
+			}
+		}
+		if len(missing) == 0 {
+			current = i
+			status.Current = ms[i].Name
+		}
+		status.Missing = append(missing, status.Missing...)
+	}
+	for _, m := range ms[current+1:] {
+		status.Pending = append(status.Pending, m.Name)
+	}
+	return status, nil
+}
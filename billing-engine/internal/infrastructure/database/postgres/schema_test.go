@@ -0,0 +1,111 @@
+package postgres
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/migrations"
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckSchema(t *testing.T) {
+	ms := []migrations.Migration{
+		{Name: "001_create_loans.sql", Up: "CREATE TABLE loans (id BIGSERIAL);"},
+		{Name: "002_add_loan_ref.sql", Up: "ALTER TABLE loans ADD COLUMN ref TEXT;"},
+		{Name: "003_touch_trigger.sql", Up: "CREATE OR REPLACE FUNCTION touch() RETURNS TRIGGER AS $$ BEGIN RETURN NEW; END; $$ LANGUAGE plpgsql;"},
+		{Name: "004_create_jobs.sql", Up: "CREATE TABLE jobs (id BIGSERIAL);\nCREATE INDEX idx_jobs_id ON jobs (id);"},
+		{Name: "005_loans_policy.sql", Up: "CREATE POLICY loans_tenant ON loans USING (true);"},
+	}
+	exists := func(mock pgxmock.PgxPoolIface, query string, found bool, args ...any) {
+		mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(args...).
+			WillReturnRows(pgxmock.NewRows([]string{"exists"}).AddRow(found))
+	}
+
+	tests := []struct {
+		name   string
+		setup  func(mock pgxmock.PgxPoolIface)
+		status SchemaStatus
+		err    error
+	}{
+		{
+			name: "current",
+			setup: func(mock pgxmock.PgxPoolIface) {
+				exists(mock, policyExistsQuery, true, "loans", "loans_tenant")
+			},
+			status: SchemaStatus{Current: "005_loans_policy.sql"},
+		},
+		{
+			name: "policy missing",
+			setup: func(mock pgxmock.PgxPoolIface) {
+				exists(mock, policyExistsQuery, false, "loans", "loans_tenant")
+				exists(mock, relationExistsQuery, true, "jobs")
+				exists(mock, relationExistsQuery, true, "idx_jobs_id")
+			},
+			status: SchemaStatus{
+				Current: "004_create_jobs.sql",
+				Pending: []string{"005_loans_policy.sql"},
+				Missing: []migrations.Object{{Relation: "loans", Policy: "loans_tenant"}},
+			},
+		},
+		{
+			name: "behind",
+			setup: func(mock pgxmock.PgxPoolIface) {
+				exists(mock, policyExistsQuery, false, "loans", "loans_tenant")
+				exists(mock, relationExistsQuery, false, "jobs")
+				exists(mock, relationExistsQuery, false, "idx_jobs_id")
+				exists(mock, columnExistsQuery, true, "loans", "ref")
+			},
+			status: SchemaStatus{
+				Current: "002_add_loan_ref.sql",
+				Pending: []string{"003_touch_trigger.sql", "004_create_jobs.sql", "005_loans_policy.sql"},
+				Missing: []migrations.Object{{Relation: "jobs"}, {Relation: "idx_jobs_id"}, {Relation: "loans", Policy: "loans_tenant"}},
+			},
+		},
+		{
+			name: "empty database",
+			setup: func(mock pgxmock.PgxPoolIface) {
+				exists(mock, policyExistsQuery, false, "loans", "loans_tenant")
+				exists(mock, relationExistsQuery, false, "jobs")
+				exists(mock, relationExistsQuery, false, "idx_jobs_id")
+				exists(mock, columnExistsQuery, false, "loans", "ref")
+				exists(mock, relationExistsQuery, false, "loans")
+			},
+			status: SchemaStatus{
+				Pending: []string{"001_create_loans.sql", "002_add_loan_ref.sql", "003_touch_trigger.sql", "004_create_jobs.sql", "005_loans_policy.sql"},
+				Missing: []migrations.Object{
+					{Relation: "loans"}, {Relation: "loans", Column: "ref"}, {Relation: "jobs"}, {Relation: "idx_jobs_id"}, {Relation: "loans", Policy: "loans_tenant"},
+				},
+			},
+		},
+		{
+			name: "database error",
+			setup: func(mock pgxmock.PgxPoolIface) {
+				mock.ExpectQuery(regexp.QuoteMeta(policyExistsQuery)).WithArgs("loans", "loans_tenant").WillReturnError(errors.New("connection reset"))
+			},
+			err: apperrors.ErrDatabase,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockPool, err := pgxmock.NewPool()
+			require.NoError(t, err)
+			defer mockPool.Close()
+			tt.setup(mockPool)
+
+			status, err := CheckSchema(context.Background(), mockPool, ms)
+
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.status, status)
+			}
+			assert.NoError(t, mockPool.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"billing-engine/internal/domain/summary"
+	"billing-engine/internal/infrastructure/monitoring"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const summaryColumns = `customer_id, customer_public_id, name, address, active, is_delinquent, loan_id, loan_public_id, loan_status,
+        principal_amount, total_loan_amount, total_paid, outstanding, installments_paid, installments_total,
+        next_due_date, next_due_amount, days_past_due, refreshed_at`
+
+// computeSummariesQuery recomputes the summary of every customer matched by
+// the WHERE clause the refresh queries append; $1 is the refresh time. The
+// next installment is the oldest unpaid one.
+const computeSummariesQuery = `
+        INSERT INTO customer_loan_summary (` + summaryColumns + `)
+        SELECT c.id, c.public_id, c.name, c.address, c.active, c.is_delinquent, l.id, l.public_id, l.status,
+               COALESCE(l.principal_amount, 0), COALESCE(l.total_loan_amount, 0),
+               COALESCE(s.total_paid, 0), COALESCE(s.outstanding, 0), COALESCE(s.installments_paid, 0), COALESCE(s.installments_total, 0),
+               n.due_date, COALESCE(n.due_amount - n.paid_amount, 0), COALESCE(l.days_past_due, 0), $1
+        FROM customers c
+        LEFT JOIN loans l ON l.id = c.loan_id
+        LEFT JOIN LATERAL (
+            SELECT SUM(paid_amount) AS total_paid,
+                   SUM(due_amount - paid_amount) FILTER (WHERE status != 'PAID') AS outstanding,
+                   COUNT(*) FILTER (WHERE status = 'PAID') AS installments_paid,
+                   COUNT(*) AS installments_total
+            FROM loan_schedule WHERE loan_id = l.id) s ON TRUE
+        LEFT JOIN LATERAL (
+            SELECT due_date, due_amount, paid_amount FROM loan_schedule
+            WHERE loan_id = l.id AND status != 'PAID'
+            ORDER BY due_date, week_number
+            LIMIT 1) n ON TRUE`
+
+const upsertSummaryClause = `
+        ON CONFLICT (customer_id) DO UPDATE SET
+            customer_public_id = EXCLUDED.customer_public_id, name = EXCLUDED.name, address = EXCLUDED.address,
+            active = EXCLUDED.active, is_delinquent = EXCLUDED.is_delinquent,
+            loan_id = EXCLUDED.loan_id, loan_public_id = EXCLUDED.loan_public_id, loan_status = EXCLUDED.loan_status,
+            principal_amount = EXCLUDED.principal_amount, total_loan_amount = EXCLUDED.total_loan_amount,
+            total_paid = EXCLUDED.total_paid, outstanding = EXCLUDED.outstanding,
+            installments_paid = EXCLUDED.installments_paid, installments_total = EXCLUDED.installments_total,
+            next_due_date = EXCLUDED.next_due_date, next_due_amount = EXCLUDED.next_due_amount,
+            days_past_due = EXCLUDED.days_past_due, refreshed_at = EXCLUDED.refreshed_at`
+
+const (
+	refreshSummaryQuery        = computeSummariesQuery + ` WHERE c.id = $2` + upsertSummaryClause
+	refreshSummaryForLoanQuery = computeSummariesQuery + ` WHERE c.loan_id = $2` + upsertSummaryClause
+	rebuildSummariesQuery      = computeSummariesQuery + upsertSummaryClause
+	getSummaryQuery            = `SELECT ` + summaryColumns + ` FROM customer_loan_summary WHERE customer_id = $1`
+)
+
+type SummaryRepository struct {
+	db     DBPool
+	clock  clock.Clock
+	logger *slog.Logger
+}
+
+var _ summary.Repository = (*SummaryRepository)(nil)
+
+// NewSummaryRepository builds the customer_loan_summary repository; clk
+// stamps refreshed_at and nil means the wall clock.
+func NewSummaryRepository(db DBPool, clk clock.Clock, logger *slog.Logger) *SummaryRepository {
+	if db == nil {
+		panic("DBPool cannot be nil for SummaryRepository")
+	}
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+		logger.Warn("Warning: No logger provided to NewSummaryRepository, using default stderr handler")
+	}
+	return &SummaryRepository{
+		db:     db,
+		clock:  clock.OrSystem(clk),
+		logger: logger.With("component", "SummaryRepository"),
+	}
+}
+
+func summaryFields(s *summary.CustomerLoanSummary) []any {
+	return []any{&s.CustomerID, &s.CustomerPublicID, &s.Name, &s.Address, &s.Active, &s.IsDelinquent,
+		&s.LoanID, &s.LoanPublicID, &s.LoanStatus, &s.PrincipalAmount, &s.TotalLoanAmount, &s.TotalPaid, &s.Outstanding,
+		&s.InstallmentsPaid, &s.InstallmentsTotal, &s.NextDueDate, &s.NextDueAmount, &s.DaysPastDue, &s.RefreshedAt}
+}
+
+func (r *SummaryRepository) Get(ctx context.Context, customerID int64) (*summary.CustomerLoanSummary, error) {
+	start := time.Now()
+	var s summary.CustomerLoanSummary
+	err := r.db.QueryRow(ctx, getSummaryQuery, customerID).Scan(summaryFields(&s)...)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			monitoring.RecordDBQuery("GetCustomerSummary", "not_found", time.Since(start))
+			return nil, fmt.Errorf("%w: no summary for customer %d", apperrors.ErrNotFound, customerID)
+		}
+		monitoring.RecordDBQuery("GetCustomerSummary", "error", time.Since(start))
+		r.logger.ErrorContext(ctx, "Failed to read customer summary", slog.Int64("customerID", customerID), slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to read customer summary: %w", apperrors.ErrDatabase, err)
+	}
+	monitoring.RecordDBQuery("GetCustomerSummary", "success", time.Since(start))
+	return &s, nil
+}
+
+func (r *SummaryRepository) Refresh(ctx context.Context, customerID int64) error {
+	n, err := r.refresh(ctx, "RefreshCustomerSummary", refreshSummaryQuery, r.clock.Now(), customerID)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: customer %d", apperrors.ErrNotFound, customerID)
+	}
+	return nil
+}
+
+func (r *SummaryRepository) RefreshForLoan(ctx context.Context, loanID int64) error {
+	_, err := r.refresh(ctx, "RefreshLoanSummary", refreshSummaryForLoanQuery, r.clock.Now(), loanID)
+	return err
+}
+
+func (r *SummaryRepository) Rebuild(ctx context.Context) (int64, error) {
+	return r.refresh(ctx, "RebuildSummaries", rebuildSummariesQuery, r.clock.Now())
+}
+
+func (r *SummaryRepository) refresh(ctx context.Context, operation, query string, args ...any) (int64, error) {
+	start := time.Now()
+	tag, err := r.db.Exec(ctx, query, args...)
+	if err != nil {
+		monitoring.RecordDBQuery(operation, "error", time.Since(start))
+		r.logger.ErrorContext(ctx, "Failed to refresh customer summaries", slog.String("operation", operation), slog.Any("error", err))
+		return 0, fmt.Errorf("%w: failed to refresh customer summaries: %w", apperrors.ErrDatabase, err)
+	}
+	monitoring.RecordDBQuery(operation, "success", time.Since(start))
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,120 @@
+package postgres
+
+import (
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupSummaryRepo(t *testing.T) (context.Context, *SummaryRepository, pgxmock.PgxPoolIface) {
+	t.Helper()
+	mockPool, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("failed to open a stub database connection: %v", err)
+	}
+	return context.Background(), NewSummaryRepository(mockPool, testClock, logger), mockPool
+}
+
+var summaryColumnNames = []string{"customer_id", "customer_public_id", "name", "address", "active", "is_delinquent",
+	"loan_id", "loan_public_id", "loan_status", "principal_amount", "total_loan_amount", "total_paid", "outstanding",
+	"installments_paid", "installments_total", "next_due_date", "next_due_amount", "days_past_due", "refreshed_at"}
+
+func TestSummaryRepositoryGet(t *testing.T) {
+	t.Run("reads the projected row", func(t *testing.T) {
+		ctx, repo, mockPool := setupSummaryRepo(t)
+		defer mockPool.Close()
+
+		loanID, status := int64(3), loan.StatusActive
+		due := time.Date(2025, 1, 13, 0, 0, 0, 0, time.UTC)
+		mockPool.ExpectQuery(regexp.QuoteMeta(getSummaryQuery)).WithArgs(int64(7)).
+			WillReturnRows(pgxmock.NewRows(summaryColumnNames).AddRow(
+				int64(7), uuid.New(), "Jane Doe", "1 Main St", true, false, &loanID, nil, &status,
+				300.0, 330.0, 110.0, 220.0, 1, 3, &due, 110.0, 0, testClock.Now()))
+
+		s, err := repo.Get(ctx, 7)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), *s.LoanID)
+		assert.Equal(t, 220.0, s.Outstanding)
+		assert.Equal(t, due, *s.NextDueDate)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("no row yet", func(t *testing.T) {
+		ctx, repo, mockPool := setupSummaryRepo(t)
+		defer mockPool.Close()
+
+		mockPool.ExpectQuery(regexp.QuoteMeta(getSummaryQuery)).WithArgs(int64(7)).
+			WillReturnRows(pgxmock.NewRows(summaryColumnNames))
+
+		_, err := repo.Get(ctx, 7)
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+}
+
+func TestSummaryRepositoryRefresh(t *testing.T) {
+	t.Run("upserts the customer's row", func(t *testing.T) {
+		ctx, repo, mockPool := setupSummaryRepo(t)
+		defer mockPool.Close()
+
+		mockPool.ExpectExec(regexp.QuoteMeta(refreshSummaryQuery)).WithArgs(testClock.Now(), int64(7)).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+		assert.NoError(t, repo.Refresh(ctx, 7))
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("unknown customer", func(t *testing.T) {
+		ctx, repo, mockPool := setupSummaryRepo(t)
+		defer mockPool.Close()
+
+		mockPool.ExpectExec(regexp.QuoteMeta(refreshSummaryQuery)).WithArgs(testClock.Now(), int64(8)).
+			WillReturnResult(pgxmock.NewResult("INSERT", 0))
+
+		assert.ErrorIs(t, repo.Refresh(ctx, 8), apperrors.ErrNotFound)
+	})
+
+	t.Run("refreshes by loan", func(t *testing.T) {
+		ctx, repo, mockPool := setupSummaryRepo(t)
+		defer mockPool.Close()
+
+		mockPool.ExpectExec(regexp.QuoteMeta(refreshSummaryForLoanQuery)).WithArgs(testClock.Now(), int64(3)).
+			WillReturnResult(pgxmock.NewResult("INSERT", 0))
+
+		assert.NoError(t, repo.RefreshForLoan(ctx, 3), "a loan no customer holds has no row to refresh")
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+}
+
+func TestSummaryRepositoryRebuild(t *testing.T) {
+	t.Run("returns the rows written", func(t *testing.T) {
+		ctx, repo, mockPool := setupSummaryRepo(t)
+		defer mockPool.Close()
+
+		mockPool.ExpectExec(regexp.QuoteMeta(rebuildSummariesQuery)).WithArgs(testClock.Now()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 42))
+
+		rows, err := repo.Rebuild(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(42), rows)
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		ctx, repo, mockPool := setupSummaryRepo(t)
+		defer mockPool.Close()
+
+		mockPool.ExpectExec(regexp.QuoteMeta(rebuildSummariesQuery)).WithArgs(testClock.Now()).
+			WillReturnError(errors.New("connection reset"))
+
+		_, err := repo.Rebuild(ctx)
+		assert.ErrorIs(t, err, apperrors.ErrDatabase)
+	})
+}
@@ -0,0 +1,60 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"billing-engine/internal/config"
+	"billing-engine/internal/pkg/scope"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// setCurrentTenantQuery names the tenant to the row-level security policies
+// for the session. An empty tenant names none, which lets them pass every
+// row.
+const setCurrentTenantQuery = `SELECT set_config('app.current_tenant', $1, false)`
+
+// bypassesRLSQuery tells whether the role the pool logs in as skips the
+// policies whatever the session names.
+const bypassesRLSQuery = `SELECT rolsuper OR rolbypassrls FROM pg_roles WHERE rolname = current_user`
+
+// NewTenantConnectionPool is NewConnectionPool for row-level security. Every
+// connection the pool hands out, for a single statement or a transaction,
+// first names the tenant of the ctx it is acquired with, so each repository
+// is held to the caller's tenant by the database as well. It refuses a role
+// the policies do not apply to, which would enforce nothing.
+func NewTenantConnectionPool(ctx context.Context, cfg config.DatabaseConfig, logger *slog.Logger) (*pgxpool.Pool, error) {
+	pool, err := newConnectionPool(ctx, cfg, nameTenant(logger), logger)
+	if err != nil {
+		return nil, err
+	}
+	var bypasses bool
+	if err := pool.QueryRow(ctx, bypassesRLSQuery).Scan(&bypasses); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to look up the database role: %w", err)
+	}
+	if bypasses {
+		pool.Close()
+		return nil, fmt.Errorf("row-level security needs a database role that is not a superuser and lacks BYPASSRLS")
+	}
+	return pool, nil
+}
+
+// nameTenant is the pool's BeforeAcquire under row-level security. An
+// unscoped ctx, such as a batch job's, names no tenant. The setting is
+// session-wide, so it is set on every acquire and never outlives the
+// caller. A connection it cannot be set on is destroyed rather than used
+// with its previous caller's tenant.
+func nameTenant(logger *slog.Logger) func(context.Context, *pgx.Conn) bool {
+	return func(ctx context.Context, conn *pgx.Conn) bool {
+		tenantID, _ := scope.TenantFromContext(ctx)
+		if _, err := conn.Exec(ctx, setCurrentTenantQuery, tenantID); err != nil {
+			logger.ErrorContext(ctx, "Failed to set the current tenant", "tenantID", tenantID, "error", err)
+			return false
+		}
+		return true
+	}
+}
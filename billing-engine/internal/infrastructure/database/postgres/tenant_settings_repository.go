@@ -0,0 +1,89 @@
+package postgres
+
+import (
+	"billing-engine/internal/domain/tenant"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	getTenantSettingsQuery = `
+        SELECT tenant_id, missed_payments, cure_payments, rate_limit_rps, rate_limit_burst, grace_days, late_fee, reminder_window_days,
+               COALESCE(updated_by, ''), updated_at
+        FROM tenant_settings
+        WHERE tenant_id = $1`
+	saveTenantSettingsQuery = `
+        INSERT INTO tenant_settings (tenant_id, missed_payments, cure_payments, rate_limit_rps, rate_limit_burst, grace_days, late_fee, reminder_window_days,
+                                     updated_by, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), $10)
+        ON CONFLICT (tenant_id) DO UPDATE SET
+            missed_payments = EXCLUDED.missed_payments,
+            cure_payments = EXCLUDED.cure_payments,
+            rate_limit_rps = EXCLUDED.rate_limit_rps,
+            rate_limit_burst = EXCLUDED.rate_limit_burst,
+            grace_days = EXCLUDED.grace_days,
+            late_fee = EXCLUDED.late_fee,
+            reminder_window_days = EXCLUDED.reminder_window_days,
+            updated_by = EXCLUDED.updated_by,
+            updated_at = EXCLUDED.updated_at`
+)
+
+type TenantSettingsRepository struct {
+	db     DBPool
+	clock  clock.Clock
+	logger *slog.Logger
+}
+
+var _ tenant.Repository = (*TenantSettingsRepository)(nil)
+
+// NewTenantSettingsRepository builds the tenant settings repository; clk
+// stamps updated_at and nil means the wall clock.
+func NewTenantSettingsRepository(db DBPool, clk clock.Clock, logger *slog.Logger) *TenantSettingsRepository {
+	if db == nil {
+		panic("DBPool cannot be nil for TenantSettingsRepository")
+	}
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+		logger.Warn("Warning: No logger provided to NewTenantSettingsRepository, using default stderr handler")
+	}
+	return &TenantSettingsRepository{
+		db:     db,
+		clock:  clock.OrSystem(clk),
+		logger: logger.With("component", "TenantSettingsRepository"),
+	}
+}
+
+func (r *TenantSettingsRepository) GetSettings(ctx context.Context, tenantID string) (*tenant.Settings, error) {
+	var s tenant.Settings
+	err := r.db.QueryRow(ctx, getTenantSettingsQuery, tenantID).Scan(&s.TenantID,
+		&s.Overrides.MissedPayments, &s.Overrides.CurePayments, &s.Overrides.RateLimitRPS, &s.Overrides.RateLimitBurst,
+		&s.Overrides.GraceDays, &s.Overrides.LateFee, &s.Overrides.ReminderWindowDays, &s.UpdatedBy, &s.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%w: settings of tenant %q", apperrors.ErrNotFound, tenantID)
+		}
+		r.logger.ErrorContext(ctx, "Failed to get tenant settings", slog.String("tenantID", tenantID), slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to get tenant settings: %w", apperrors.ErrDatabase, err)
+	}
+	return &s, nil
+}
+
+func (r *TenantSettingsRepository) SaveSettings(ctx context.Context, s *tenant.Settings) error {
+	updatedAt := r.clock.Now()
+	_, err := r.db.Exec(ctx, saveTenantSettingsQuery, s.TenantID,
+		s.Overrides.MissedPayments, s.Overrides.CurePayments, s.Overrides.RateLimitRPS, s.Overrides.RateLimitBurst,
+		s.Overrides.GraceDays, s.Overrides.LateFee, s.Overrides.ReminderWindowDays, s.UpdatedBy, updatedAt)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to save tenant settings", slog.String("tenantID", s.TenantID), slog.Any("error", err))
+		return fmt.Errorf("%w: failed to save tenant settings: %w", apperrors.ErrDatabase, err)
+	}
+	s.UpdatedAt = updatedAt
+	return nil
+}
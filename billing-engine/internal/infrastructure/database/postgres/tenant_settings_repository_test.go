@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"billing-engine/internal/domain/tenant"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupTenantSettingsRepo(t *testing.T) (context.Context, *TenantSettingsRepository, pgxmock.PgxPoolIface) {
+	t.Helper()
+	mockPool, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("failed to open a stub database connection: %v", err)
+	}
+	return context.Background(), NewTenantSettingsRepository(mockPool, testClock, logger), mockPool
+}
+
+func TestTenantSettingsRepositoryGetSettings(t *testing.T) {
+	columns := []string{"tenant_id", "missed_payments", "cure_payments", "rate_limit_rps", "rate_limit_burst", "grace_days", "late_fee", "reminder_window_days", "updated_by", "updated_at"}
+
+	t.Run("stored settings", func(t *testing.T) {
+		ctx, repo, mockPool := setupTenantSettingsRepo(t)
+		defer mockPool.Close()
+		updatedAt := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+		missed, rps, lateFee := 3, 2.5, 15.0
+		mockPool.ExpectQuery(regexp.QuoteMeta(getTenantSettingsQuery)).WithArgs(tenant.DefaultTenant).
+			WillReturnRows(pgxmock.NewRows(columns).AddRow(tenant.DefaultTenant, &missed, (*int)(nil), &rps, (*int)(nil), (*int)(nil), &lateFee, (*int)(nil), "ops", updatedAt))
+
+		s, err := repo.GetSettings(ctx, tenant.DefaultTenant)
+		require.NoError(t, err)
+		assert.Equal(t, &tenant.Settings{TenantID: tenant.DefaultTenant, Overrides: tenant.Overrides{MissedPayments: &missed, RateLimitRPS: &rps, LateFee: &lateFee},
+			UpdatedBy: "ops", UpdatedAt: updatedAt}, s)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("none stored", func(t *testing.T) {
+		ctx, repo, mockPool := setupTenantSettingsRepo(t)
+		defer mockPool.Close()
+		mockPool.ExpectQuery(regexp.QuoteMeta(getTenantSettingsQuery)).WithArgs(tenant.DefaultTenant).WillReturnError(pgx.ErrNoRows)
+
+		_, err := repo.GetSettings(ctx, tenant.DefaultTenant)
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+}
+
+func TestTenantSettingsRepositorySaveSettings(t *testing.T) {
+	t.Run("upserts and stamps updated_at", func(t *testing.T) {
+		ctx, repo, mockPool := setupTenantSettingsRepo(t)
+		defer mockPool.Close()
+		burst, window := 5, 14
+		mockPool.ExpectExec(regexp.QuoteMeta(saveTenantSettingsQuery)).
+			WithArgs(tenant.DefaultTenant, (*int)(nil), (*int)(nil), (*float64)(nil), &burst, (*int)(nil), (*float64)(nil), &window, "ops", testClock.Now()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+		s := &tenant.Settings{TenantID: tenant.DefaultTenant, Overrides: tenant.Overrides{RateLimitBurst: &burst, ReminderWindowDays: &window}, UpdatedBy: "ops"}
+		require.NoError(t, repo.SaveSettings(ctx, s))
+		assert.True(t, s.UpdatedAt.Equal(testClock.Now()))
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		ctx, repo, mockPool := setupTenantSettingsRepo(t)
+		defer mockPool.Close()
+		mockPool.ExpectExec(regexp.QuoteMeta(saveTenantSettingsQuery)).
+			WithArgs(tenant.DefaultTenant, (*int)(nil), (*int)(nil), (*float64)(nil), (*int)(nil), (*int)(nil), (*float64)(nil), (*int)(nil), "", testClock.Now()).
+			WillReturnError(errors.New("connection reset"))
+
+		err := repo.SaveSettings(ctx, &tenant.Settings{TenantID: tenant.DefaultTenant})
+		assert.ErrorIs(t, err, apperrors.ErrDatabase)
+	})
+}
@@ -0,0 +1,152 @@
+// Package sqlbuilder assembles SELECT statements whose conditions, ordering
+// and paging depend on optional filters. Values are always bound as numbered
+// parameters; the only text spliced into a statement is SQL written by the
+// repositories themselves, and sort columns come from a whitelist.
+//
+// Both the PostgreSQL and the SQLite driver accept $n placeholders, so one
+// builder serves both backends.
+package sqlbuilder
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SelectBuilder collects the optional parts of a query. The first error,
+// such as an unknown sort field, is kept and returned by Build.
+type SelectBuilder struct {
+	base    string
+	where   []string
+	args    []any
+	orderBy string
+	limit   int
+	offset  int
+	err     error
+}
+
+// Select starts a statement from base, which holds the SELECT list and FROM
+// clause and neither WHERE, ORDER BY nor placeholders.
+func Select(base string) *SelectBuilder {
+	return &SelectBuilder{base: strings.TrimSpace(base)}
+}
+
+// Where adds a condition joined to the others with AND. Each ? in cond is
+// bound to the next value of args, in order.
+func (b *SelectBuilder) Where(cond string, args ...any) *SelectBuilder {
+	if b.err != nil {
+		return b
+	}
+	if n := strings.Count(cond, "?"); n != len(args) {
+		b.err = fmt.Errorf("%w: condition %q has %d placeholders for %d values", apperrors.ErrInternalServer, cond, n, len(args))
+		return b
+	}
+	var sb strings.Builder
+	for _, part := range strings.SplitAfter(cond, "?") {
+		if !strings.HasSuffix(part, "?") {
+			sb.WriteString(part)
+			continue
+		}
+		b.args = append(b.args, args[0])
+		args = args[1:]
+		sb.WriteString(part[:len(part)-1])
+		sb.WriteString("$" + strconv.Itoa(len(b.args)))
+	}
+	b.where = append(b.where, sb.String())
+	return b
+}
+
+// WhereIf adds the condition only when ok is true, for filters that are off
+// by default.
+func (b *SelectBuilder) WhereIf(ok bool, cond string, args ...any) *SelectBuilder {
+	if !ok {
+		return b
+	}
+	return b.Where(cond, args...)
+}
+
+// OrderBy sorts by the column columns maps sort to, descending when sort
+// starts with "-", and then by tiebreak so that pages are stable. An empty
+// sort orders by tiebreak alone; a field missing from columns is
+// ErrInvalidArgument.
+func (b *SelectBuilder) OrderBy(sort string, columns map[string]string, tiebreak string) *SelectBuilder {
+	if b.err != nil {
+		return b
+	}
+	field, desc := strings.CutPrefix(strings.TrimSpace(sort), "-")
+	if field == "" {
+		b.orderBy = tiebreak
+		return b
+	}
+	column, ok := columns[field]
+	if !ok {
+		b.err = fmt.Errorf("%w: cannot sort by %q", apperrors.ErrInvalidArgument, field)
+		return b
+	}
+	b.orderBy = column
+	if desc {
+		b.orderBy += " DESC"
+	}
+	if column != tiebreak {
+		b.orderBy += ", " + tiebreak
+	}
+	return b
+}
+
+// Page limits the result to limit rows after skipping offset. A limit of
+// zero returns every row. SQLite has no OFFSET without LIMIT, so an offset
+// needs a limit.
+func (b *SelectBuilder) Page(limit, offset int) *SelectBuilder {
+	if b.err != nil {
+		return b
+	}
+	if limit < 0 || offset < 0 {
+		b.err = fmt.Errorf("%w: limit and offset cannot be negative", apperrors.ErrInvalidArgument)
+		return b
+	}
+	if offset > 0 && limit == 0 {
+		b.err = fmt.Errorf("%w: offset needs a limit", apperrors.ErrInvalidArgument)
+		return b
+	}
+	b.limit, b.offset = limit, offset
+	return b
+}
+
+// Build returns the statement and the values for its placeholders.
+func (b *SelectBuilder) Build() (string, []any, error) {
+	if b.err != nil {
+		return "", nil, b.err
+	}
+	var sb strings.Builder
+	sb.WriteString(b.base)
+	if len(b.where) > 0 {
+		sb.WriteString(" WHERE ")
+		sb.WriteString(strings.Join(b.where, " AND "))
+	}
+	if b.orderBy != "" {
+		sb.WriteString(" ORDER BY ")
+		sb.WriteString(b.orderBy)
+	}
+	args := b.args
+	if b.limit > 0 {
+		args = append(args[:len(args):len(args)], b.limit)
+		sb.WriteString(" LIMIT $" + strconv.Itoa(len(args)))
+	}
+	if b.offset > 0 {
+		args = append(args[:len(args):len(args)], b.offset)
+		sb.WriteString(" OFFSET $" + strconv.Itoa(len(args)))
+	}
+	return sb.String(), args, nil
+}
+
+// likeEscaper escapes the LIKE wildcards, and the escape character itself,
+// for patterns compared with ESCAPE '\'.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// Contains returns the LIKE pattern matching the values that contain s. The
+// condition it is bound to must declare ESCAPE '\', which SQLite has no
+// default for.
+func Contains(s string) string {
+	return "%" + likeEscaper.Replace(s) + "%"
+}
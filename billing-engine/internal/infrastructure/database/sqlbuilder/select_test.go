@@ -0,0 +1,59 @@
+package sqlbuilder
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var sortColumns = map[string]string{"id": "id", "name": "name", "created_at": "created_at"}
+
+func TestSelectBuild(t *testing.T) {
+	active := true
+	query, args, err := Select(`SELECT id, name FROM customers`).
+		WhereIf(active, "active = ?", true).
+		WhereIf(false, "is_delinquent = ?", true).
+		Where("created_at BETWEEN ? AND ?", "2025-01-01", "2025-02-01").
+		OrderBy("-name", sortColumns, "id").
+		Page(20, 40).
+		Build()
+
+	require.NoError(t, err)
+	assert.Equal(t, `SELECT id, name FROM customers WHERE active = $1 AND created_at BETWEEN $2 AND $3 ORDER BY name DESC, id LIMIT $4 OFFSET $5`, query)
+	assert.Equal(t, []any{true, "2025-01-01", "2025-02-01", 20, 40}, args)
+}
+
+func TestSelectWithoutOptionalParts(t *testing.T) {
+	query, args, err := Select("SELECT id FROM loans\n").OrderBy("", sortColumns, "id").Build()
+
+	require.NoError(t, err)
+	assert.Equal(t, `SELECT id FROM loans ORDER BY id`, query)
+	assert.Empty(t, args)
+
+	query, _, err = Select(`SELECT id FROM loans`).OrderBy("-id", sortColumns, "id").Build()
+	require.NoError(t, err)
+	assert.Equal(t, `SELECT id FROM loans ORDER BY id DESC`, query, "the tiebreak is not repeated")
+}
+
+func TestSelectRejects(t *testing.T) {
+	for name, b := range map[string]*SelectBuilder{
+		"sort field outside the whitelist": Select(`SELECT id FROM customers`).OrderBy("name; DROP TABLE customers", sortColumns, "id"),
+		"negative limit":                   Select(`SELECT id FROM customers`).Page(-1, 0),
+		"offset without limit":             Select(`SELECT id FROM customers`).Page(0, 10),
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := b.Build()
+			assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+		})
+	}
+
+	_, _, err := Select(`SELECT id FROM customers`).Where("active = ? AND id > ?", true).Build()
+	assert.ErrorIs(t, err, apperrors.ErrInternalServer, "placeholders and values must match")
+}
+
+func TestContains(t *testing.T) {
+	assert.Equal(t, `%doe%`, Contains("doe"))
+	assert.Equal(t, `%50\% off\_a\\b%`, Contains(`50% off_a\b`))
+}
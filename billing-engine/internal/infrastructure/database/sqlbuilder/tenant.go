@@ -0,0 +1,27 @@
+package sqlbuilder
+
+import (
+	"context"
+	"strconv"
+
+	"billing-engine/internal/pkg/scope"
+)
+
+// WhereTenant limits the statement to the rows of ctx's tenant, where
+// column holds the tenant, when ctx is scoped to one.
+func (b *SelectBuilder) WhereTenant(ctx context.Context, column string) *SelectBuilder {
+	tenantID, ok := scope.TenantFromContext(ctx)
+	return b.WhereIf(ok, column+" = ?", tenantID)
+}
+
+// ScopeToTenant does what WhereTenant does for a fixed statement: when ctx
+// is scoped to a tenant it appends "AND column = $n" to query, which must
+// end in its WHERE clause, and the tenant to args as $n.
+func ScopeToTenant(ctx context.Context, query, column string, args ...any) (string, []any) {
+	tenantID, ok := scope.TenantFromContext(ctx)
+	if !ok {
+		return query, args
+	}
+	args = append(args, tenantID)
+	return query + " AND " + column + " = $" + strconv.Itoa(len(args)), args
+}
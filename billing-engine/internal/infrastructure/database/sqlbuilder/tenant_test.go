@@ -0,0 +1,38 @@
+package sqlbuilder
+
+import (
+	"billing-engine/internal/pkg/scope"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWhereTenant(t *testing.T) {
+	ctx := scope.WithTenant(context.Background(), "acme")
+
+	query, args, err := Select(`SELECT id FROM loans`).Where("id > ?", 5).WhereTenant(ctx, "tenant_id").OrderBy("", nil, "id").Build()
+
+	require.NoError(t, err)
+	assert.Equal(t, `SELECT id FROM loans WHERE id > $1 AND tenant_id = $2 ORDER BY id`, query)
+	assert.Equal(t, []any{5, "acme"}, args)
+
+	query, args, err = Select(`SELECT id FROM loans`).WhereTenant(context.Background(), "tenant_id").Build()
+
+	require.NoError(t, err)
+	assert.Equal(t, `SELECT id FROM loans`, query)
+	assert.Empty(t, args)
+}
+
+func TestScopeToTenant(t *testing.T) {
+	const base = `SELECT id FROM loans WHERE id = $1`
+
+	query, args := ScopeToTenant(scope.WithTenant(context.Background(), "acme"), base, "l.tenant_id", int64(7))
+	assert.Equal(t, base+` AND l.tenant_id = $2`, query)
+	assert.Equal(t, []any{int64(7), "acme"}, args)
+
+	query, args = ScopeToTenant(context.Background(), base, "l.tenant_id", int64(7))
+	assert.Equal(t, base, query)
+	assert.Equal(t, []any{int64(7)}, args)
+}
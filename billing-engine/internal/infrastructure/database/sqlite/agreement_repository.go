@@ -0,0 +1,103 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"billing-engine/internal/domain/agreement"
+	"billing-engine/internal/pkg/apperrors"
+
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+const agreementColumns = `id, loan_id, document_id, hold_id, provider, envelope_id, status, COALESCE(sent_by, ''), sent_at, updated_at,
+        signed_at, COALESCE(disbursed_by, ''), disbursed_at`
+
+type AgreementRepository struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+var _ agreement.Repository = (*AgreementRepository)(nil)
+
+func NewAgreementRepository(db *sql.DB, logger *slog.Logger) *AgreementRepository {
+	return &AgreementRepository{db: db, logger: logger.With("component", "AgreementRepository")}
+}
+
+func (r *AgreementRepository) Create(ctx context.Context, a *agreement.Agreement) error {
+	sentAt := a.SentAt.UTC()
+	err := r.db.QueryRowContext(ctx, `
+        INSERT INTO loan_agreements (loan_id, document_id, hold_id, provider, envelope_id, status, sent_by, sent_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $8)
+        RETURNING id`, a.LoanID, a.DocumentID, a.HoldID, a.Provider, a.EnvelopeID, a.Status, a.SentBy, sentAt).Scan(&a.ID)
+	switch code := sqliteCode(err); {
+	case err == nil:
+		a.UpdatedAt = sentAt
+		return nil
+	case code == sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
+		return fmt.Errorf("%w: loan %d", apperrors.ErrNotFound, a.LoanID)
+	case code == sqlite3.SQLITE_CONSTRAINT_UNIQUE:
+		return fmt.Errorf("%w: loan %d already has an open agreement, or envelope %s is recorded", apperrors.ErrConflict, a.LoanID, a.EnvelopeID)
+	}
+	r.logger.ErrorContext(ctx, "Failed to insert agreement", slog.Int64("loanID", a.LoanID), slog.Any("error", err))
+	return fmt.Errorf("%w: failed to insert agreement: %w", apperrors.ErrDatabase, err)
+}
+
+func (r *AgreementRepository) GetLatest(ctx context.Context, loanID int64) (*agreement.Agreement, error) {
+	return r.getAgreement(ctx, fmt.Sprintf("agreement of loan %d", loanID),
+		`SELECT `+agreementColumns+` FROM loan_agreements WHERE loan_id = $1 ORDER BY sent_at DESC, id DESC LIMIT 1`, loanID)
+}
+
+func (r *AgreementRepository) GetByEnvelope(ctx context.Context, provider, envelopeID string) (*agreement.Agreement, error) {
+	return r.getAgreement(ctx, fmt.Sprintf("envelope %s", envelopeID),
+		`SELECT `+agreementColumns+` FROM loan_agreements WHERE provider = $1 AND envelope_id = $2`, provider, envelopeID)
+}
+
+func (r *AgreementRepository) getAgreement(ctx context.Context, what, query string, args ...any) (*agreement.Agreement, error) {
+	var a agreement.Agreement
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(&a.ID, &a.LoanID, &a.DocumentID, &a.HoldID, &a.Provider, &a.EnvelopeID, &a.Status,
+		&a.SentBy, &a.SentAt, &a.UpdatedAt, &a.SignedAt, &a.DisbursedBy, &a.DisbursedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: %s", apperrors.ErrNotFound, what)
+		}
+		r.logger.ErrorContext(ctx, "Failed to get agreement", slog.String("agreement", what), slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to get agreement: %w", apperrors.ErrDatabase, err)
+	}
+	return &a, nil
+}
+
+func (r *AgreementRepository) UpdateStatus(ctx context.Context, agreementID int64, status agreement.Status, at time.Time) error {
+	res, err := r.db.ExecContext(ctx, `
+        UPDATE loan_agreements
+        SET status = $2, updated_at = $3, signed_at = CASE WHEN $2 = 'COMPLETED' THEN $3 END
+        WHERE id = $1 AND status = 'SENT'`, agreementID, status, at.UTC())
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to update agreement status", slog.Int64("agreementID", agreementID), slog.Any("error", err))
+		return fmt.Errorf("%w: failed to update agreement: %w", apperrors.ErrDatabase, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: agreement %d is no longer awaiting signature", apperrors.ErrConflict, agreementID)
+	}
+	return nil
+}
+
+func (r *AgreementRepository) MarkDisbursed(ctx context.Context, agreementID int64, disbursedBy string, at time.Time) error {
+	res, err := r.db.ExecContext(ctx, `
+        UPDATE loan_agreements SET disbursed_by = NULLIF($2, ''), disbursed_at = $3, updated_at = $3
+        WHERE id = $1 AND status = 'COMPLETED' AND disbursed_at IS NULL`, agreementID, disbursedBy, at.UTC())
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to record disbursement", slog.Int64("agreementID", agreementID), slog.Any("error", err))
+		return fmt.Errorf("%w: failed to update agreement: %w", apperrors.ErrDatabase, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: agreement %d is not signed or its loan was disbursed already", apperrors.ErrConflict, agreementID)
+	}
+	return nil
+}
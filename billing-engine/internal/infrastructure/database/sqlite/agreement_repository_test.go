@@ -0,0 +1,66 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"billing-engine/internal/domain/agreement"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgreementRepository(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewAgreementRepository(db, testLogger)
+	ctx := context.Background()
+
+	_, l := createTestLoan(t, db, day("2025-01-06"), "ref-workflow")
+	sentAt := time.Date(2025, 1, 3, 9, 0, 0, 0, time.UTC)
+
+	_, err := repo.GetLatest(ctx, l.ID)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+
+	first := &agreement.Agreement{LoanID: l.ID, Provider: "http", EnvelopeID: "env-1", Status: agreement.StatusSent, SentBy: "ops", SentAt: sentAt}
+	require.NoError(t, repo.Create(ctx, first))
+	assert.NotZero(t, first.ID)
+	err = repo.Create(ctx, &agreement.Agreement{LoanID: l.ID, Provider: "http", EnvelopeID: "env-2", Status: agreement.StatusSent, SentAt: sentAt})
+	assert.ErrorIs(t, err, apperrors.ErrConflict, "a loan has one open agreement")
+	err = repo.Create(ctx, &agreement.Agreement{LoanID: 999, Provider: "http", EnvelopeID: "env-3", Status: agreement.StatusSent, SentAt: sentAt})
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+
+	require.NoError(t, repo.UpdateStatus(ctx, first.ID, agreement.StatusDeclined, sentAt.Add(time.Hour)))
+	assert.ErrorIs(t, repo.UpdateStatus(ctx, first.ID, agreement.StatusCompleted, sentAt.Add(time.Hour)), apperrors.ErrConflict)
+
+	second := &agreement.Agreement{LoanID: l.ID, Provider: "http", EnvelopeID: "env-2", Status: agreement.StatusSent, SentAt: sentAt.Add(2 * time.Hour)}
+	require.NoError(t, repo.Create(ctx, second), "a declined agreement is followed by a new one")
+	assert.ErrorIs(t, repo.MarkDisbursed(ctx, second.ID, "ops", sentAt.Add(3*time.Hour)), apperrors.ErrConflict, "an unsigned loan is not disbursed")
+
+	signedAt := sentAt.Add(3 * time.Hour)
+	require.NoError(t, repo.UpdateStatus(ctx, second.ID, agreement.StatusCompleted, signedAt))
+	latest, err := repo.GetLatest(ctx, l.ID)
+	require.NoError(t, err)
+	assert.Equal(t, second.ID, latest.ID)
+	assert.Equal(t, agreement.StatusCompleted, latest.Status)
+	require.NotNil(t, latest.SignedAt)
+	assert.True(t, signedAt.Equal(*latest.SignedAt))
+	assert.Equal(t, "", latest.SentBy)
+
+	require.NoError(t, repo.MarkDisbursed(ctx, second.ID, "ops", signedAt.Add(time.Hour)))
+	assert.ErrorIs(t, repo.MarkDisbursed(ctx, second.ID, "ops", signedAt.Add(time.Hour)), apperrors.ErrConflict)
+
+	byEnvelope, err := repo.GetByEnvelope(ctx, "http", "env-1")
+	require.NoError(t, err)
+	assert.Equal(t, agreement.StatusDeclined, byEnvelope.Status)
+	assert.Nil(t, byEnvelope.SignedAt)
+	assert.Equal(t, "ops", byEnvelope.SentBy)
+	disbursed, err := repo.GetByEnvelope(ctx, "http", "env-2")
+	require.NoError(t, err)
+	assert.Equal(t, "ops", disbursed.DisbursedBy)
+	assert.NotNil(t, disbursed.DisbursedAt)
+	_, err = repo.GetByEnvelope(ctx, "other", "env-1")
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+}
@@ -0,0 +1,143 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"billing-engine/internal/domain/collections"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollectionsRepository(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewCollectionsRepository(db, clock.System(), testLogger)
+	loans := NewLoanRepository(db, clock.System(), testLogger)
+	ctx := context.Background()
+	customerID, created := createTestLoan(t, db, day("2025-01-06"), "")
+
+	minOutstanding := 100.0
+	rule := &collections.Rule{Name: "Early", Collector: "alice", Bucket: "1-30", Region: "main st", MinOutstanding: &minOutstanding, Priority: 1, Active: true}
+	require.NoError(t, repo.CreateRule(ctx, rule))
+	rules, err := repo.ListRules(ctx)
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	assert.Equal(t, "1-30", rules[0].Bucket)
+	assert.Equal(t, 100.0, *rules[0].MinOutstanding)
+	assert.Nil(t, rules[0].MaxOutstanding)
+
+	candidates, err := repo.ListCandidates(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, candidates, "loans that are not past due are not candidates")
+
+	require.NoError(t, loans.UpdateDaysPastDue(ctx, created.ID, 12))
+	candidates, err = repo.ListCandidates(ctx)
+	require.NoError(t, err)
+	require.Len(t, candidates, 1)
+	assert.Equal(t, customerID, candidates[0].CustomerID)
+	assert.Equal(t, "1 Main St", candidates[0].Address)
+	assert.Equal(t, 330.0, candidates[0].Outstanding)
+
+	assignment := &collections.Assignment{LoanID: created.ID, CustomerID: customerID, Collector: "alice", RuleID: &rule.ID,
+		Status: collections.AssignmentOpen, AssignedAt: day("2025-01-20")}
+	require.NoError(t, repo.CreateAssignment(ctx, assignment))
+	again := *assignment
+	assert.ErrorIs(t, repo.CreateAssignment(ctx, &again), apperrors.ErrAlreadyExists)
+	candidates, err = repo.ListCandidates(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, candidates, "assigned loans are not candidates")
+
+	promised := day("2025-01-24")
+	amount := 110.0
+	ptp := &collections.Action{AssignmentID: assignment.ID, LoanID: created.ID, Collector: "alice", Type: collections.ActionPromiseToPay,
+		PromisedAmount: &amount, PromisedDate: &promised}
+	require.NoError(t, repo.CreateAction(ctx, ptp))
+
+	open, err := repo.GetOpenAssignmentForLoan(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, assignment.ID, open.ID)
+
+	queue, err := repo.ListOpenAssignments(ctx, "alice")
+	require.NoError(t, err)
+	require.Len(t, queue, 1)
+	assert.Equal(t, "Jane Doe", queue[0].CustomerName)
+	assert.Equal(t, 12, queue[0].DaysPastDue)
+	assert.NotNil(t, queue[0].LastActionAt)
+
+	reassigned := &collections.Action{AssignmentID: assignment.ID, LoanID: created.ID, Collector: "supervisor", Type: collections.ActionReassigned, Note: "from alice to bob"}
+	require.NoError(t, repo.Reassign(ctx, assignment.ID, "bob", reassigned))
+	queue, err = repo.ListOpenAssignments(ctx, "alice")
+	require.NoError(t, err)
+	assert.Empty(t, queue)
+
+	actions, err := repo.ListActions(ctx, assignment.ID)
+	require.NoError(t, err)
+	require.Len(t, actions, 2)
+	assert.Equal(t, collections.ActionReassigned, actions[0].Type)
+	assert.Equal(t, promised, *actions[1].PromisedDate)
+
+	require.NoError(t, repo.ResolveAssignment(ctx, assignment.ID, collections.ResolutionCured, day("2025-01-27")))
+	assert.ErrorIs(t, repo.ResolveAssignment(ctx, assignment.ID, collections.ResolutionCured, day("2025-01-27")), apperrors.ErrConflict)
+	assert.ErrorIs(t, repo.Reassign(ctx, assignment.ID, "carol", reassigned), apperrors.ErrConflict)
+	got, err := repo.GetAssignment(ctx, assignment.ID)
+	require.NoError(t, err)
+	assert.Equal(t, collections.ResolutionCured, got.Resolution)
+	_, err = repo.GetOpenAssignmentForLoan(ctx, created.ID)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound, "resolved assignments are not open")
+	assert.True(t, got.ResolvedAt.Equal(day("2025-01-27")))
+
+	require.NoError(t, repo.DeleteRule(ctx, rule.ID))
+	assert.ErrorIs(t, repo.DeleteRule(ctx, rule.ID), apperrors.ErrNotFound)
+}
+
+func TestCollectionsRepositoryEscalations(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewCollectionsRepository(db, clock.System(), testLogger)
+	loans := NewLoanRepository(db, clock.System(), testLogger)
+	ctx := context.Background()
+	customerID, created := createTestLoan(t, db, day("2025-01-06"), "")
+
+	steps, err := repo.ListEscalationSteps(ctx)
+	require.NoError(t, err)
+	require.Len(t, steps, 4, "the schema seeds the default ladder")
+	assert.Equal(t, collections.ReminderSMS, steps[0].Action)
+
+	require.NoError(t, repo.ReplaceEscalationSteps(ctx, []collections.EscalationStep{
+		{DaysPastDue: 3, Action: collections.ReminderEmail}, {DaysPastDue: 10, Action: collections.ReminderCall},
+	}))
+	steps, err = repo.ListEscalationSteps(ctx)
+	require.NoError(t, err)
+	require.Len(t, steps, 2)
+	assert.Equal(t, 10, steps[1].DaysPastDue)
+	assert.False(t, steps[1].CreatedAt.IsZero())
+
+	escalations, err := repo.ListEscalations(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, escalations, "loans that are not past due are not escalated")
+
+	require.NoError(t, loans.UpdateDaysPastDue(ctx, created.ID, 4))
+	require.NoError(t, repo.SetEscalation(ctx, created.ID, 3, day("2025-01-20")))
+	require.NoError(t, repo.SetEscalation(ctx, created.ID, 3, day("2025-01-21")), "storing a step again updates it")
+	escalations, err = repo.ListEscalations(ctx)
+	require.NoError(t, err)
+	require.Len(t, escalations, 1)
+	assert.Equal(t, "default", escalations[0].TenantID)
+	assert.Equal(t, customerID, escalations[0].CustomerID)
+	assert.Equal(t, 4, escalations[0].DaysPastDue)
+	require.NotNil(t, escalations[0].LastStep)
+	assert.Equal(t, 3, *escalations[0].LastStep)
+
+	require.NoError(t, loans.UpdateDaysPastDue(ctx, created.ID, 0))
+	escalations, err = repo.ListEscalations(ctx)
+	require.NoError(t, err)
+	require.Len(t, escalations, 1, "a loan that reached a step stays listed until it is cleared")
+
+	require.NoError(t, repo.ClearEscalation(ctx, created.ID))
+	escalations, err = repo.ListEscalations(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, escalations)
+}
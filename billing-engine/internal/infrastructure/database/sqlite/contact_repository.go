@@ -0,0 +1,209 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"billing-engine/internal/domain/contact"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+const contactColumns = `id, customer_id, type, value, verified, is_primary, verified_at, created_at, updated_at`
+
+type ContactRepository struct {
+	db     *sql.DB
+	clock  clock.Clock
+	logger *slog.Logger
+}
+
+var _ contact.Repository = (*ContactRepository)(nil)
+
+func NewContactRepository(db *sql.DB, clk clock.Clock, logger *slog.Logger) *ContactRepository {
+	return &ContactRepository{db: db, clock: clock.OrSystem(clk), logger: logger.With("component", "ContactRepository")}
+}
+
+func contactFields(c *contact.Contact) []any {
+	return []any{&c.ID, &c.CustomerID, &c.Type, &c.Value, &c.Verified, &c.Primary, &c.VerifiedAt, &c.CreatedAt, &c.UpdatedAt}
+}
+
+// contactError maps the constraint errors a write of c can hit.
+func contactError(err error, c *contact.Contact) error {
+	switch sqliteCode(err) {
+	case sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
+		return fmt.Errorf("%w: customer %d", apperrors.ErrNotFound, c.CustomerID)
+	case sqlite3.SQLITE_CONSTRAINT_UNIQUE:
+		return fmt.Errorf("%w: customer %d already has the %s %s", apperrors.ErrConflict, c.CustomerID, c.Type, c.Value)
+	}
+	return nil
+}
+
+func (r *ContactRepository) Create(ctx context.Context, c *contact.Contact) error {
+	createdAt := now(r.clock)
+	c.Verified, c.Primary, c.VerifiedAt = false, false, nil
+	err := r.db.QueryRowContext(ctx, `
+        INSERT INTO customer_contacts (customer_id, type, value, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $4)
+        RETURNING id`, c.CustomerID, c.Type, c.Value, createdAt).Scan(&c.ID)
+	if err != nil {
+		if mapped := contactError(err, c); mapped != nil {
+			return mapped
+		}
+		r.logger.ErrorContext(ctx, "Failed to insert contact", slog.Int64("customerID", c.CustomerID), slog.Any("error", err))
+		return fmt.Errorf("%w: failed to insert contact: %w", apperrors.ErrDatabase, err)
+	}
+	c.CreatedAt = createdAt
+	c.UpdatedAt = createdAt
+	return nil
+}
+
+func (r *ContactRepository) List(ctx context.Context, customerID int64) ([]contact.Contact, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+contactColumns+` FROM customer_contacts WHERE customer_id = $1 ORDER BY type, is_primary DESC, id`, customerID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query contacts", slog.Int64("customerID", customerID), slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to list contacts: %w", apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	contacts := []contact.Contact{}
+	for rows.Next() {
+		var c contact.Contact
+		if err := rows.Scan(contactFields(&c)...); err != nil {
+			return nil, fmt.Errorf("%w: failed to scan contact: %w", apperrors.ErrDatabase, err)
+		}
+		contacts = append(contacts, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: failed to iterate contacts: %w", apperrors.ErrDatabase, err)
+	}
+	return contacts, nil
+}
+
+func (r *ContactRepository) Get(ctx context.Context, customerID, contactID int64) (*contact.Contact, error) {
+	var c contact.Contact
+	err := r.db.QueryRowContext(ctx,
+		`SELECT `+contactColumns+` FROM customer_contacts WHERE id = $1 AND customer_id = $2`, contactID, customerID).Scan(contactFields(&c)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: contact %d of customer %d", apperrors.ErrNotFound, contactID, customerID)
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to get contact", slog.Int64("contactID", contactID), slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to get contact: %w", apperrors.ErrDatabase, err)
+	}
+	return &c, nil
+}
+
+func (r *ContactRepository) Update(ctx context.Context, c *contact.Contact) error {
+	logger := r.logger.With(slog.Int64("customerID", c.CustomerID), slog.Int64("contactID", c.ID))
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to begin transaction", slog.Any("error", err))
+		return fmt.Errorf("%w: failed to begin transaction: %w", apperrors.ErrDatabase, err)
+	}
+	defer tx.Rollback()
+
+	updatedAt := now(r.clock)
+	if c.Primary {
+		if _, err := tx.ExecContext(ctx, `UPDATE customer_contacts SET is_primary = FALSE, updated_at = $1
+            WHERE customer_id = $2 AND type = $3 AND id != $4 AND is_primary`, updatedAt, c.CustomerID, c.Type, c.ID); err != nil {
+			logger.ErrorContext(ctx, "Failed to clear other primary contact", slog.Any("error", err))
+			return fmt.Errorf("%w: failed to clear other primary contact: %w", apperrors.ErrDatabase, err)
+		}
+	}
+	res, err := tx.ExecContext(ctx, `UPDATE customer_contacts SET value = $1, verified = $2, is_primary = $3, verified_at = $4, updated_at = $5
+        WHERE id = $6 AND customer_id = $7`, c.Value, c.Verified, c.Primary, timeArg(c.VerifiedAt), updatedAt, c.ID, c.CustomerID)
+	if err != nil {
+		if mapped := contactError(err, c); mapped != nil {
+			return mapped
+		}
+		logger.ErrorContext(ctx, "Failed to update contact", slog.Any("error", err))
+		return fmt.Errorf("%w: failed to update contact: %w", apperrors.ErrDatabase, err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("%w: %w", apperrors.ErrDatabase, err)
+	} else if n == 0 {
+		return fmt.Errorf("%w: contact %d of customer %d", apperrors.ErrNotFound, c.ID, c.CustomerID)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%w: failed to commit contact update: %w", apperrors.ErrDatabase, err)
+	}
+	c.UpdatedAt = updatedAt
+	return nil
+}
+
+func (r *ContactRepository) Delete(ctx context.Context, customerID, contactID int64) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM customer_contacts WHERE id = $1 AND customer_id = $2`, contactID, customerID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to delete contact", slog.Int64("contactID", contactID), slog.Any("error", err))
+		return fmt.Errorf("%w: failed to delete contact: %w", apperrors.ErrDatabase, err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("%w: %w", apperrors.ErrDatabase, err)
+	} else if n == 0 {
+		return fmt.Errorf("%w: contact %d of customer %d", apperrors.ErrNotFound, contactID, customerID)
+	}
+	return nil
+}
+
+func (r *ContactRepository) SaveVerification(ctx context.Context, v *contact.Verification) error {
+	_, err := r.db.ExecContext(ctx, `
+        INSERT INTO customer_contact_verifications (contact_id, value, code_hash, expires_at, attempts, sent_at)
+        VALUES ($1, $2, $3, $4, 0, $5)
+        ON CONFLICT (contact_id) DO UPDATE
+        SET value = excluded.value, code_hash = excluded.code_hash, expires_at = excluded.expires_at, attempts = 0, sent_at = excluded.sent_at`,
+		v.ContactID, v.Value, v.CodeHash, v.ExpiresAt.UTC(), v.SentAt.UTC())
+	if err != nil {
+		if sqliteCode(err) == sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY {
+			return fmt.Errorf("%w: contact %d", apperrors.ErrNotFound, v.ContactID)
+		}
+		r.logger.ErrorContext(ctx, "Failed to save verification", slog.Int64("contactID", v.ContactID), slog.Any("error", err))
+		return fmt.Errorf("%w: failed to save verification: %w", apperrors.ErrDatabase, err)
+	}
+	v.Attempts = 0
+	return nil
+}
+
+func (r *ContactRepository) GetVerification(ctx context.Context, contactID int64) (*contact.Verification, error) {
+	var v contact.Verification
+	err := r.db.QueryRowContext(ctx,
+		`SELECT contact_id, value, code_hash, expires_at, attempts, sent_at FROM customer_contact_verifications WHERE contact_id = $1`, contactID).
+		Scan(&v.ContactID, &v.Value, &v.CodeHash, &v.ExpiresAt, &v.Attempts, &v.SentAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: no verification pending for contact %d", apperrors.ErrNotFound, contactID)
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to get verification", slog.Int64("contactID", contactID), slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to get verification: %w", apperrors.ErrDatabase, err)
+	}
+	return &v, nil
+}
+
+func (r *ContactRepository) CountFailedAttempt(ctx context.Context, contactID int64) (int, error) {
+	var attempts int
+	err := r.db.QueryRowContext(ctx,
+		`UPDATE customer_contact_verifications SET attempts = attempts + 1 WHERE contact_id = $1 RETURNING attempts`, contactID).Scan(&attempts)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("%w: no verification pending for contact %d", apperrors.ErrNotFound, contactID)
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to count verification attempt", slog.Int64("contactID", contactID), slog.Any("error", err))
+		return 0, fmt.Errorf("%w: failed to count verification attempt: %w", apperrors.ErrDatabase, err)
+	}
+	return attempts, nil
+}
+
+func (r *ContactRepository) DeleteVerification(ctx context.Context, contactID int64) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM customer_contact_verifications WHERE contact_id = $1`, contactID); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to delete verification", slog.Int64("contactID", contactID), slog.Any("error", err))
+		return fmt.Errorf("%w: failed to delete verification: %w", apperrors.ErrDatabase, err)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"billing-engine/internal/domain/contact"
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContactRepository(t *testing.T) {
+	db := openTestDB(t)
+	clk := clock.NewFake(time.Date(2025, 3, 5, 9, 0, 0, 0, time.UTC))
+	repo := NewContactRepository(db, clk, testLogger)
+	ctx := context.Background()
+	cust := customer.NewCustomer("Ayu Lestari", "2 Jalan Merdeka")
+	require.NoError(t, NewCustomerRepository(db, clk, testLogger).Save(ctx, cust))
+
+	first := &contact.Contact{CustomerID: cust.CustomerID, Type: contact.TypeEmail, Value: "ayu@example.com"}
+	second := &contact.Contact{CustomerID: cust.CustomerID, Type: contact.TypeEmail, Value: "ayu@work.example.com"}
+	require.NoError(t, repo.Create(ctx, first))
+	require.NoError(t, repo.Create(ctx, second))
+	assert.ErrorIs(t, repo.Create(ctx, &contact.Contact{CustomerID: cust.CustomerID, Type: contact.TypeEmail, Value: "ayu@example.com"}), apperrors.ErrConflict)
+	assert.ErrorIs(t, repo.Create(ctx, &contact.Contact{CustomerID: 999, Type: contact.TypeEmail, Value: "x@example.com"}), apperrors.ErrNotFound)
+
+	verifiedAt := clk.Now()
+	for _, c := range []*contact.Contact{first, second} {
+		c.Verified, c.VerifiedAt, c.Primary = true, &verifiedAt, true
+		require.NoError(t, repo.Update(ctx, c), "the second takes the primary flag from the first")
+	}
+	contacts, err := repo.List(ctx, cust.CustomerID)
+	require.NoError(t, err)
+	require.Len(t, contacts, 2)
+	assert.Equal(t, second.ID, contacts[0].ID, "primary first")
+	assert.False(t, contacts[1].Primary)
+	assert.True(t, contacts[1].VerifiedAt.Equal(verifiedAt))
+
+	v := &contact.Verification{ContactID: first.ID, Value: first.Value, CodeHash: contact.HashCode("482913"), ExpiresAt: clk.Now().Add(contact.CodeTTL), SentAt: clk.Now()}
+	require.NoError(t, repo.SaveVerification(ctx, v))
+	attempts, err := repo.CountFailedAttempt(ctx, first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, attempts)
+	require.NoError(t, repo.SaveVerification(ctx, v), "a new code resets the attempts")
+	stored, err := repo.GetVerification(ctx, first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, stored.Attempts)
+	assert.True(t, stored.ExpiresAt.Equal(v.ExpiresAt))
+
+	require.NoError(t, repo.Delete(ctx, cust.CustomerID, first.ID))
+	_, err = repo.GetVerification(ctx, first.ID)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound, "the code goes with its contact")
+	assert.ErrorIs(t, repo.Delete(ctx, cust.CustomerID, first.ID), apperrors.ErrNotFound)
+	_, err = repo.Get(ctx, cust.CustomerID, first.ID)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+}
@@ -0,0 +1,67 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/scope"
+
+	"github.com/google/uuid"
+)
+
+var _ customer.ImportRepository = (*CustomerRepository)(nil)
+
+const insertImportedCustomerQuery = `
+        INSERT INTO customers (public_id, name, address, external_ref, is_delinquent, active, created_at, updated_at, tenant_id)
+        VALUES ($1, $2, $3, $4, FALSE, TRUE, $5, $5, $6)
+        ON CONFLICT (external_ref) DO NOTHING
+        RETURNING id`
+
+// InsertImportBatch inserts the rows one by one in a single transaction, so
+// a database error rolls back the whole chunk as the PostgreSQL batch does.
+// The customers belong to ctx's tenant.
+func (r *CustomerRepository) InsertImportBatch(ctx context.Context, rows []customer.ImportRow) ([]int64, error) {
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to begin import batch: %w", apperrors.ErrDatabase, err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, insertImportedCustomerQuery)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to prepare import insert: %w", apperrors.ErrDatabase, err)
+	}
+	defer stmt.Close()
+
+	createdAt := now(r.clock)
+	tenantID := scope.TenantOrDefault(ctx)
+	ids := make([]int64, len(rows))
+	for i, row := range rows {
+		err := stmt.QueryRowContext(ctx, uuid.New(), row.Name, row.Address, row.ExternalRef, createdAt, tenantID).Scan(&ids[i])
+		if errors.Is(err, sql.ErrNoRows) {
+			ids[i] = 0
+			continue
+		}
+		if err != nil {
+			r.logger.ErrorContext(ctx, "Failed to insert imported customer", slog.Int("line", row.Line), slog.Any("error", err))
+			return nil, fmt.Errorf("%w: failed to insert imported customer on line %d: %w", apperrors.ErrDatabase, row.Line, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%w: failed to complete import batch: %w", apperrors.ErrDatabase, err)
+	}
+	r.logger.InfoContext(ctx, "Imported customer batch inserted", slog.Int("rows", len(rows)))
+	return ids, nil
+}
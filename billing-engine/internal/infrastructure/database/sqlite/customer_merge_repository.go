@@ -0,0 +1,108 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// mergeRelinks point the source's records, $2, at the target, $1, as the
+// Postgres repository's do.
+var mergeRelinks = []struct {
+	query string
+	count func(*customer.MergedRecords) *int64
+}{
+	{`UPDATE notes SET customer_id = $1 WHERE customer_id = $2`, func(m *customer.MergedRecords) *int64 { return &m.Notes }},
+	{`UPDATE attachments SET customer_id = $1 WHERE customer_id = $2`, func(m *customer.MergedRecords) *int64 { return &m.Attachments }},
+	{`UPDATE documents SET customer_id = $1 WHERE customer_id = $2`, func(m *customer.MergedRecords) *int64 { return &m.Documents }},
+	{`UPDATE mandates SET customer_id = $1 WHERE customer_id = $2`, func(m *customer.MergedRecords) *int64 { return &m.Mandates }},
+	{`UPDATE collection_assignments SET customer_id = $1 WHERE customer_id = $2`, func(m *customer.MergedRecords) *int64 { return &m.CollectionAssignments }},
+	{`UPDATE collection_assignments_archive SET customer_id = $1 WHERE customer_id = $2`, func(m *customer.MergedRecords) *int64 { return &m.CollectionAssignments }},
+	{`UPDATE archived_loans SET customer_id = $1 WHERE customer_id = $2`, func(m *customer.MergedRecords) *int64 { return &m.ArchivedLoans }},
+	{`UPDATE event_log SET entity_id = $1 WHERE entity_id = $2`, func(m *customer.MergedRecords) *int64 { return &m.Events }},
+}
+
+// MergeCustomers has no row locks to take: SQLite lets one writer in at a
+// time, and the transaction's first write waits for it.
+func (r *CustomerRepository) MergeCustomers(ctx context.Context, targetID, sourceID int64) (*customer.Merge, error) {
+	logger := r.logger.With(slog.Int64("targetID", targetID), slog.Int64("sourceID", sourceID))
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to begin transaction", slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to begin transaction: %w", apperrors.ErrDatabase, err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `SELECT `+customerColumns+` FROM customers WHERE id IN ($1, $2)`, targetID, sourceID)
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to read customers for merge", slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to read customers for merge: %w", apperrors.ErrDatabase, err)
+	}
+	customers, err := scanCustomers(rows)
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+	var target, source *customer.Customer
+	for _, c := range customers {
+		switch c.CustomerID {
+		case targetID:
+			target = c
+		case sourceID:
+			source = c
+		}
+	}
+	switch {
+	case target == nil:
+		return nil, fmt.Errorf("%w: customer %d", apperrors.ErrNotFound, targetID)
+	case source == nil:
+		return nil, fmt.Errorf("%w: customer %d", apperrors.ErrNotFound, sourceID)
+	}
+	if err := customer.CheckMerge(target, source); err != nil {
+		return nil, err
+	}
+	var withMandate int
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(DISTINCT customer_id) FROM mandates WHERE customer_id IN ($1, $2) AND status = 'ACTIVE'`,
+		targetID, sourceID).Scan(&withMandate); err != nil {
+		logger.ErrorContext(ctx, "Failed to count active mandates", slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to count active mandates: %w", apperrors.ErrDatabase, err)
+	}
+	if withMandate > 1 {
+		return nil, fmt.Errorf("%w: customers %d and %d both have an active mandate", apperrors.ErrConflict, targetID, sourceID)
+	}
+
+	at := now(r.clock)
+	// The source lets go of its loan first, since loan_id is unique.
+	if _, err := tx.ExecContext(ctx, `UPDATE customers SET loan_id = NULL, active = FALSE, merged_into_id = $1, merged_at = $2, updated_at = $2 WHERE id = $3`,
+		targetID, at, sourceID); err != nil {
+		logger.ErrorContext(ctx, "Failed to mark customer merged", slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to mark customer merged: %w", apperrors.ErrDatabase, err)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE customers SET loan_id = COALESCE(loan_id, $1), is_delinquent = is_delinquent OR $2, updated_at = $3 WHERE id = $4`,
+		source.LoanID, source.IsDelinquent, at, targetID); err != nil {
+		logger.ErrorContext(ctx, "Failed to move loan to merge target", slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to move loan to merge target: %w", apperrors.ErrDatabase, err)
+	}
+	merge := customer.NewMerge(target, source, at)
+	for _, relink := range mergeRelinks {
+		res, err := tx.ExecContext(ctx, relink.query, targetID, sourceID)
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to re-link merged records", slog.String("query", relink.query), slog.Any("error", err))
+			return nil, fmt.Errorf("%w: failed to re-link merged records: %w", apperrors.ErrDatabase, err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to count re-linked records: %w", apperrors.ErrDatabase, err)
+		}
+		*relink.count(&merge.Moved) += n
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%w: failed to commit customer merge: %w", apperrors.ErrDatabase, err)
+	}
+	logger.InfoContext(ctx, "Customers merged successfully")
+	return merge, nil
+}
@@ -0,0 +1,59 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/domain/directdebit"
+	"billing-engine/internal/domain/note"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCustomerRepositoryMergeCustomers(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewCustomerRepository(db, clock.System(), testLogger)
+	ctx := context.Background()
+
+	sourceID, created := createTestLoan(t, db, day("2025-01-06"), "")
+	target := customer.NewCustomer("Jane Doe", "1 Main St")
+	require.NoError(t, repo.Save(ctx, target))
+	require.NoError(t, NewNoteRepository(db, clock.System(), testLogger).CreateNote(ctx,
+		&note.Note{Subject: note.Subject{Type: note.SubjectCustomer, ID: sourceID}, Body: "Imported twice"}))
+	require.NoError(t, NewDirectDebitRepository(db, clock.System(), testLogger).CreateMandate(ctx, &directdebit.Mandate{
+		CustomerID: sourceID, Reference: "MNDT-1", AccountHolder: "Jane Doe", AccountNumber: "9876543210",
+		BankCode: "BANKIDJB", Status: directdebit.MandateActive, SignedAt: day("2025-01-02"),
+	}))
+	_, err := db.ExecContext(ctx, `INSERT INTO event_log (event_id, event_type, entity_id, payload, occurred_at)
+        VALUES ('e-1', 'customer.created', $1, '{}', $2)`, sourceID, now(clock.System()))
+	require.NoError(t, err)
+
+	merge, err := repo.MergeCustomers(ctx, target.CustomerID, sourceID)
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, *merge.LoanID)
+	assert.Equal(t, customer.MergedRecords{Notes: 1, Mandates: 1, Events: 1}, merge.Moved)
+
+	stored, err := repo.FindByLoanID(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, target.CustomerID, stored.CustomerID, "the loan moved to the target")
+	source, err := repo.FindByID(ctx, sourceID)
+	require.NoError(t, err)
+	assert.False(t, source.Active)
+	assert.Nil(t, source.LoanID)
+	assert.Equal(t, target.CustomerID, *source.MergedIntoID)
+	assert.True(t, merge.Source.MergedAt.Equal(*source.MergedAt))
+
+	_, err = repo.MergeCustomers(ctx, target.CustomerID, sourceID)
+	assert.ErrorIs(t, err, customer.ErrAlreadyMerged)
+
+	otherID, _ := createTestLoan(t, db, day("2025-01-06"), "")
+	_, err = repo.MergeCustomers(ctx, target.CustomerID, otherID)
+	assert.ErrorIs(t, err, apperrors.ErrConflict, "both hold a loan")
+	_, err = repo.MergeCustomers(ctx, target.CustomerID, 999)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+}
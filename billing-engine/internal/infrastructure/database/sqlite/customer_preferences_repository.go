@@ -0,0 +1,58 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+func (r *CustomerRepository) GetPreferences(ctx context.Context, customerID int64) (*customer.Preferences, error) {
+	var p customer.Preferences
+	var updatedAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, `
+        SELECT c.id, COALESCE(p.preferred_channel, ''), COALESCE(p.language, ''),
+               COALESCE(p.marketing_opt_out, FALSE), COALESCE(p.transactional_opt_out, FALSE), p.updated_at
+        FROM customers c LEFT JOIN customer_preferences p ON p.customer_id = c.id
+        WHERE c.id = $1`, customerID).Scan(
+		&p.CustomerID, &p.PreferredChannel, &p.Language, &p.MarketingOptOut, &p.TransactionalOptOut, &updatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: customer %d", apperrors.ErrNotFound, customerID)
+		}
+		r.logger.ErrorContext(ctx, "Failed to get customer preferences", slog.Int64("customerID", customerID), slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to get customer preferences: %w", apperrors.ErrDatabase, err)
+	}
+	p.UpdatedAt = updatedAt.Time
+	return &p, nil
+}
+
+func (r *CustomerRepository) SavePreferences(ctx context.Context, p *customer.Preferences) error {
+	updatedAt := now(r.clock)
+	_, err := r.db.ExecContext(ctx, `
+        INSERT INTO customer_preferences (customer_id, preferred_channel, language, marketing_opt_out, transactional_opt_out, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        ON CONFLICT (customer_id) DO UPDATE SET
+            preferred_channel = excluded.preferred_channel,
+            language = excluded.language,
+            marketing_opt_out = excluded.marketing_opt_out,
+            transactional_opt_out = excluded.transactional_opt_out,
+            updated_at = excluded.updated_at`,
+		p.CustomerID, p.PreferredChannel, p.Language, p.MarketingOptOut, p.TransactionalOptOut, updatedAt)
+	if err != nil {
+		if sqliteCode(err) == sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY {
+			return fmt.Errorf("%w: customer %d", apperrors.ErrNotFound, p.CustomerID)
+		}
+		r.logger.ErrorContext(ctx, "Failed to save customer preferences", slog.Int64("customerID", p.CustomerID), slog.Any("error", err))
+		return fmt.Errorf("%w: failed to save customer preferences: %w", apperrors.ErrDatabase, err)
+	}
+	p.UpdatedAt = updatedAt
+	return nil
+}
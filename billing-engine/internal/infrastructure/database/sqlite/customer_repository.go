@@ -0,0 +1,343 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/infrastructure/database/sqlbuilder"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"billing-engine/internal/pkg/scope"
+
+	"github.com/google/uuid"
+)
+
+const customerColumns = `id, public_id, name, address, is_delinquent, active, loan_id, external_ref, risk_score, risk_grade, risk_scored_at, merged_into_id, merged_at, created_at, updated_at`
+
+type CustomerRepository struct {
+	db     *sql.DB
+	clock  clock.Clock
+	logger *slog.Logger
+}
+
+var _ customer.CustomerRepository = (*CustomerRepository)(nil)
+
+func NewCustomerRepository(db *sql.DB, clk clock.Clock, logger *slog.Logger) *CustomerRepository {
+	return &CustomerRepository{db: db, clock: clock.OrSystem(clk), logger: logger.With("component", "CustomerRepository")}
+}
+
+func scanCustomer(row rowScanner, cust *customer.Customer) error {
+	return row.Scan(
+		&cust.CustomerID,
+		&cust.PublicID,
+		&cust.Name,
+		&cust.Address,
+		&cust.IsDelinquent,
+		&cust.Active,
+		&cust.LoanID,
+		&cust.ExternalRef,
+		&cust.RiskScore,
+		&cust.RiskGrade,
+		&cust.RiskScoredAt,
+		&cust.MergedIntoID,
+		&cust.MergedAt,
+		&cust.CreateDate,
+		&cust.UpdatedAt,
+	)
+}
+
+func (r *CustomerRepository) Save(ctx context.Context, cust *customer.Customer) error {
+	if cust == nil {
+		return fmt.Errorf("%w: customer cannot be nil", apperrors.ErrInvalidArgument)
+	}
+	if cust.CustomerID == 0 {
+		return r.createCustomer(ctx, cust)
+	}
+	return r.updateCustomer(ctx, cust)
+}
+
+func (r *CustomerRepository) createCustomer(ctx context.Context, cust *customer.Customer) error {
+	if cust.PublicID == uuid.Nil {
+		cust.PublicID = uuid.New()
+	}
+	createdAt := now(r.clock)
+
+	err := r.db.QueryRowContext(ctx, `
+        INSERT INTO customers (public_id, name, address, is_delinquent, active, loan_id, external_ref, created_at, updated_at, tenant_id)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8, $9)
+        RETURNING id`,
+		cust.PublicID, cust.Name, cust.Address, cust.IsDelinquent, cust.Active, cust.LoanID, cust.ExternalRef, createdAt, scope.TenantOrDefault(ctx),
+	).Scan(&cust.CustomerID)
+	if err != nil {
+		if translated := translateDBError(err, r.logger); errors.Is(translated, apperrors.ErrAlreadyExists) {
+			return translated
+		}
+		r.logger.ErrorContext(ctx, "Failed to insert customer", slog.Any("error", err))
+		return fmt.Errorf("%w: failed to insert customer: %w", apperrors.ErrDatabase, err)
+	}
+
+	cust.CreateDate = createdAt
+	cust.UpdatedAt = createdAt
+	r.logger.InfoContext(ctx, "Customer inserted successfully", slog.Int64("customerID", cust.CustomerID))
+	return nil
+}
+
+func (r *CustomerRepository) updateCustomer(ctx context.Context, cust *customer.Customer) error {
+	res, err := r.execScoped(ctx, `
+        UPDATE customers
+        SET name = $1,
+            address = $2,
+            is_delinquent = $3,
+            active = $4,
+            loan_id = $5,
+            updated_at = $6
+        WHERE id = $7`,
+		cust.Name, cust.Address, cust.IsDelinquent, cust.Active, cust.LoanID, now(r.clock), cust.CustomerID,
+	)
+	if err != nil {
+		if translated := translateDBError(err, r.logger); errors.Is(translated, apperrors.ErrAlreadyExists) {
+			return translated
+		}
+		r.logger.ErrorContext(ctx, "Failed to update customer", slog.Any("error", err))
+		return fmt.Errorf("%w: failed to update customer: %w", apperrors.ErrDatabase, err)
+	}
+	return r.requireRow(ctx, res, "Update affected zero rows, customer likely not found")
+}
+
+// execScoped runs a statement that ends in its WHERE clause on the
+// customers of ctx's tenant only.
+func (r *CustomerRepository) execScoped(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	query, args = sqlbuilder.ScopeToTenant(ctx, query, "tenant_id", args...)
+	return r.db.ExecContext(ctx, query, args...)
+}
+
+// requireRow turns a statement that matched no customer into ErrNotFound.
+func (r *CustomerRepository) requireRow(ctx context.Context, res sql.Result, warning string) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%w: %w", apperrors.ErrDatabase, err)
+	}
+	if n == 0 {
+		r.logger.WarnContext(ctx, warning)
+		return apperrors.ErrNotFound
+	}
+	return nil
+}
+
+// findOne reads the customer of ctx's tenant where column equals arg. column
+// is one of a fixed set of names, never input.
+func (r *CustomerRepository) findOne(ctx context.Context, column string, arg any) (*customer.Customer, error) {
+	query, args := sqlbuilder.ScopeToTenant(ctx, `SELECT `+customerColumns+` FROM customers WHERE `+column+` = $1`, "tenant_id", arg)
+	var cust customer.Customer
+	err := scanCustomer(r.db.QueryRowContext(ctx, query, args...), &cust)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			r.logger.WarnContext(ctx, "Customer not found", "by", column)
+			return nil, apperrors.ErrNotFound
+		}
+		r.logger.ErrorContext(ctx, "Failed to query/scan customer", "by", column, slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to get customer by %s: %w", apperrors.ErrDatabase, column, err)
+	}
+	return &cust, nil
+}
+
+func (r *CustomerRepository) FindByID(ctx context.Context, customerID int64) (*customer.Customer, error) {
+	return r.findOne(ctx, "id", customerID)
+}
+
+func (r *CustomerRepository) FindByPublicID(ctx context.Context, publicID uuid.UUID) (*customer.Customer, error) {
+	return r.findOne(ctx, "public_id", publicID)
+}
+
+func (r *CustomerRepository) FindByLoanID(ctx context.Context, loanID int64) (*customer.Customer, error) {
+	return r.findOne(ctx, "loan_id", loanID)
+}
+
+func (r *CustomerRepository) FindByExternalRef(ctx context.Context, externalRef string) (*customer.Customer, error) {
+	return r.findOne(ctx, "external_ref", externalRef)
+}
+
+var customerSortColumns = map[string]string{"id": "id", "name": "name", "created_at": "created_at", "updated_at": "updated_at"}
+
+func (r *CustomerRepository) FindAll(ctx context.Context, filter customer.ListFilter) ([]*customer.Customer, error) {
+	q := sqlbuilder.Select(`SELECT `+customerColumns+` FROM customers`).WhereTenant(ctx, "tenant_id")
+	if filter.Active != nil {
+		q.Where("active = ?", *filter.Active)
+	}
+	if filter.Delinquent != nil {
+		q.Where("is_delinquent = ?", *filter.Delinquent)
+	}
+	if filter.AfterID > 0 {
+		q.Where("id > ?", filter.AfterID)
+	}
+	query, args, err := q.OrderBy(filter.Sort, customerSortColumns, "id").Page(filter.Limit, filter.Offset).Build()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query customers", slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to query customers: %w", apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+	return scanCustomers(rows)
+}
+
+func scanCustomers(rows *sql.Rows) ([]*customer.Customer, error) {
+	customers := make([]*customer.Customer, 0)
+	for rows.Next() {
+		var cust customer.Customer
+		if err := scanCustomer(rows, &cust); err != nil {
+			return nil, fmt.Errorf("%w: failed to scan customer row: %w", apperrors.ErrDatabase, err)
+		}
+		customers = append(customers, &cust)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: error iterating customer rows: %w", apperrors.ErrDatabase, err)
+	}
+	return customers, nil
+}
+
+func (r *CustomerRepository) FindByNameContaining(ctx context.Context, part string, limit int) ([]*customer.Customer, error) {
+	query, args, err := sqlbuilder.Select(`SELECT `+customerColumns+` FROM customers`).
+		WhereTenant(ctx, "tenant_id").
+		Where("active = ?", true).
+		Where(`lower(name) LIKE lower(?) ESCAPE '\'`, sqlbuilder.Contains(part)).
+		OrderBy("", nil, "id").
+		Page(limit, 0).
+		Build()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query customers by name", slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to query customers by name: %w", apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+	return scanCustomers(rows)
+}
+
+func (r *CustomerRepository) Delete(ctx context.Context, customerID int64) error {
+	res, err := r.execScoped(ctx, `DELETE FROM customers WHERE id = $1`, customerID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to execute delete customer", slog.Any("error", err))
+		return fmt.Errorf("%w: failed to delete customer: %w", apperrors.ErrDatabase, err)
+	}
+	return r.requireRow(ctx, res, "Delete affected zero rows, customer likely not found")
+}
+
+func (r *CustomerRepository) SetDelinquencyStatus(ctx context.Context, customerID int64, isDelinquent bool) error {
+	res, err := r.execScoped(ctx, `UPDATE customers SET is_delinquent = $1, updated_at = $2 WHERE id = $3`, isDelinquent, now(r.clock), customerID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to execute update delinquency status", slog.Any("error", err))
+		return fmt.Errorf("%w: failed to update delinquency status: %w", apperrors.ErrDatabase, err)
+	}
+	return r.requireRow(ctx, res, "Update delinquency affected zero rows, customer likely not found")
+}
+
+func (r *CustomerRepository) SetRiskScore(ctx context.Context, a *customer.RiskAssessment) error {
+	res, err := r.execScoped(ctx, `UPDATE customers SET risk_score = $1, risk_grade = $2, risk_scored_at = $3, updated_at = $4 WHERE id = $5`,
+		a.Score, a.Grade, a.ScoredAt.UTC(), now(r.clock), a.CustomerID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to execute update risk score", slog.Any("error", err))
+		return fmt.Errorf("%w: failed to update risk score: %w", apperrors.ErrDatabase, err)
+	}
+	return r.requireRow(ctx, res, "Update risk score affected zero rows, customer likely not found")
+}
+
+// SetDelinquencyStatusBulk passes the updates as one JSON array of
+// [id, flag] pairs, since SQLite has no array parameters and caps the number
+// of placeholders.
+func (r *CustomerRepository) SetDelinquencyStatusBulk(ctx context.Context, updates []customer.CustomerDelinquency) ([]*customer.Customer, error) {
+	pairs := make([][2]any, len(updates))
+	for i, u := range updates {
+		pairs[i] = [2]any{u.CustomerID, u.IsDelinquent}
+	}
+	payload, err := json.Marshal(pairs)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to encode delinquency updates: %w", apperrors.ErrInternalServer, err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+        UPDATE customers SET is_delinquent = json_extract(v.value, '$[1]'), updated_at = $2
+        FROM json_each($1) AS v
+        WHERE customers.id = json_extract(v.value, '$[0]') AND customers.is_delinquent <> json_extract(v.value, '$[1]')
+        RETURNING `+customerColumns, string(payload), now(r.clock))
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to execute bulk update delinquency status", slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to update delinquency statuses: %w", apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+	return scanCustomers(rows)
+}
+
+// MarkDelinquencyNotified passes the notices as [id, bucket] pairs, the way
+// SetDelinquencyStatusBulk passes its updates.
+func (r *CustomerRepository) MarkDelinquencyNotified(ctx context.Context, notices []customer.DelinquencyNotice) ([]int64, error) {
+	pairs := make([][2]any, len(notices))
+	for i, n := range notices {
+		pairs[i] = [2]any{n.CustomerID, n.Bucket}
+	}
+	payload, err := json.Marshal(pairs)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to encode delinquency notices: %w", apperrors.ErrInternalServer, err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+        UPDATE customers SET notified_for_dpd_bucket = json_extract(v.value, '$[1]'), last_notified_delinquency_at = $2
+        FROM json_each($1) AS v
+        WHERE customers.id = json_extract(v.value, '$[0]') AND customers.notified_for_dpd_bucket IS NOT json_extract(v.value, '$[1]')
+        RETURNING customers.id`, string(payload), now(r.clock))
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to execute mark delinquency notified", slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to record delinquency notices: %w", apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	notified := make([]int64, 0)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("%w: failed scanning notified customer ID: %w", apperrors.ErrDatabase, err)
+		}
+		notified = append(notified, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: error iterating notified customer IDs: %w", apperrors.ErrDatabase, err)
+	}
+	return notified, nil
+}
+
+func (r *CustomerRepository) ClearDelinquencyNotices(ctx context.Context, customerIDs []int64) error {
+	ids, err := json.Marshal(customerIDs)
+	if err != nil {
+		return fmt.Errorf("%w: failed to encode customer IDs: %w", apperrors.ErrInternalServer, err)
+	}
+	_, err = r.db.ExecContext(ctx, `
+        UPDATE customers SET notified_for_dpd_bucket = NULL
+        WHERE id IN (SELECT value FROM json_each($1)) AND notified_for_dpd_bucket IS NOT NULL`, string(ids))
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to execute clear delinquency notices", slog.Any("error", err))
+		return fmt.Errorf("%w: failed to clear delinquency notices: %w", apperrors.ErrDatabase, err)
+	}
+	return nil
+}
+
+func (r *CustomerRepository) SetActiveStatus(ctx context.Context, customerID int64, isActive bool) error {
+	res, err := r.execScoped(ctx, `UPDATE customers SET active = $1, updated_at = $2 WHERE id = $3`, isActive, now(r.clock), customerID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to execute update active status", slog.Any("error", err))
+		return fmt.Errorf("%w: failed to update active status: %w", apperrors.ErrDatabase, err)
+	}
+	return r.requireRow(ctx, res, "Update active status affected zero rows, customer likely not found")
+}
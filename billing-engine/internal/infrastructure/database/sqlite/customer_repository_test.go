@@ -0,0 +1,265 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCustomerRepository(t *testing.T) {
+	repo := NewCustomerRepository(openTestDB(t), clock.System(), testLogger)
+	ctx := context.Background()
+
+	ref := "crm-1"
+	cust := customer.NewCustomer("Jane Doe", "1 Main St")
+	cust.ExternalRef = &ref
+	require.NoError(t, repo.Save(ctx, cust))
+	require.NotZero(t, cust.CustomerID)
+
+	found, err := repo.FindByPublicID(ctx, cust.PublicID)
+	require.NoError(t, err)
+	assert.Equal(t, "Jane Doe", found.Name)
+	assert.True(t, found.Active)
+	assert.False(t, found.IsDelinquent)
+	assert.Nil(t, found.LoanID)
+	assert.True(t, cust.CreateDate.Equal(found.CreateDate))
+
+	found, err = repo.FindByExternalRef(ctx, ref)
+	require.NoError(t, err)
+	assert.Equal(t, cust.CustomerID, found.CustomerID)
+
+	duplicate := customer.NewCustomer("John Doe", "2 Main St")
+	duplicate.ExternalRef = &ref
+	assert.ErrorIs(t, repo.Save(ctx, duplicate), apperrors.ErrAlreadyExists)
+
+	found.Address = "3 Main St"
+	require.NoError(t, repo.Save(ctx, found))
+	require.NoError(t, repo.SetDelinquencyStatus(ctx, cust.CustomerID, true))
+	require.NoError(t, repo.SetActiveStatus(ctx, cust.CustomerID, false))
+
+	found, err = repo.FindByID(ctx, cust.CustomerID)
+	require.NoError(t, err)
+	assert.Equal(t, "3 Main St", found.Address)
+	assert.True(t, found.IsDelinquent)
+	assert.False(t, found.Active)
+
+	activeOnly := true
+	active, err := repo.FindAll(ctx, customer.ListFilter{Active: &activeOnly})
+	require.NoError(t, err)
+	assert.Empty(t, active)
+	all, err := repo.FindAll(ctx, customer.ListFilter{})
+	require.NoError(t, err)
+	assert.Len(t, all, 1)
+
+	require.NoError(t, repo.Delete(ctx, cust.CustomerID))
+	assert.ErrorIs(t, repo.Delete(ctx, cust.CustomerID), apperrors.ErrNotFound)
+	assert.ErrorIs(t, repo.SetActiveStatus(ctx, cust.CustomerID, true), apperrors.ErrNotFound)
+	_, err = repo.FindByID(ctx, cust.CustomerID)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+}
+
+func TestCustomerRepositoryInsertImportBatch(t *testing.T) {
+	repo := NewCustomerRepository(openTestDB(t), clock.System(), testLogger)
+	ctx := context.Background()
+
+	ids, err := repo.InsertImportBatch(ctx, []customer.ImportRow{
+		{Line: 2, Name: "Jane Doe", Address: "1 Main St", ExternalRef: "crm-1"},
+		{Line: 3, Name: "John Doe", Address: "2 Main St", ExternalRef: "crm-2"},
+	})
+	require.NoError(t, err)
+	require.Len(t, ids, 2)
+	assert.NotZero(t, ids[0])
+	assert.NotZero(t, ids[1])
+
+	ids, err = repo.InsertImportBatch(ctx, []customer.ImportRow{
+		{Line: 2, Name: "Jane Doe", Address: "1 Main St", ExternalRef: "crm-1"},
+		{Line: 3, Name: "Max Doe", Address: "4 Main St", ExternalRef: "crm-3"},
+	})
+	require.NoError(t, err)
+	assert.Zero(t, ids[0], "existing references are skipped")
+	assert.NotZero(t, ids[1])
+
+	imported, err := repo.FindByExternalRef(ctx, "crm-3")
+	require.NoError(t, err)
+	assert.Equal(t, ids[1], imported.CustomerID)
+	assert.True(t, imported.Active)
+}
+
+func TestCustomerRepositoryTimestampsFromClock(t *testing.T) {
+	clk := clock.NewFake(time.Date(2025, 1, 6, 9, 30, 0, 0, time.UTC))
+	repo := NewCustomerRepository(openTestDB(t), clk, testLogger)
+	ctx := context.Background()
+
+	cust := customer.NewCustomer("Jane Doe", "1 Main St")
+	require.NoError(t, repo.Save(ctx, cust))
+
+	clk.Advance(time.Hour)
+	require.NoError(t, repo.SetDelinquencyStatus(ctx, cust.CustomerID, true))
+
+	stored, err := repo.FindByID(ctx, cust.CustomerID)
+	require.NoError(t, err)
+	assert.True(t, stored.CreateDate.Equal(time.Date(2025, 1, 6, 9, 30, 0, 0, time.UTC)))
+	assert.True(t, stored.UpdatedAt.Equal(time.Date(2025, 1, 6, 10, 30, 0, 0, time.UTC)))
+}
+
+func TestCustomerRepositoryFindAllSortsAndPages(t *testing.T) {
+	repo := NewCustomerRepository(openTestDB(t), clock.System(), testLogger)
+	ctx := context.Background()
+	for _, name := range []string{"Bob", "Carol", "Alice"} {
+		require.NoError(t, repo.Save(ctx, customer.NewCustomer(name, "1 Main St")))
+	}
+
+	page, err := repo.FindAll(ctx, customer.ListFilter{Sort: "-name", Limit: 1, Offset: 1})
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, "Bob", page[0].Name)
+
+	page, err = repo.FindAll(ctx, customer.ListFilter{AfterID: 1, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, "Carol", page[0].Name)
+
+	_, err = repo.FindAll(ctx, customer.ListFilter{Sort: "address"})
+	assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+}
+
+func TestCustomerRepositoryFindByNameContaining(t *testing.T) {
+	repo := NewCustomerRepository(openTestDB(t), clock.System(), testLogger)
+	ctx := context.Background()
+	customers := map[string]*customer.Customer{}
+	for _, name := range []string{"Budi Santoso", "SANTOSO, Ayu", "Budi_Santoso", "Siti Rahayu"} {
+		customers[name] = customer.NewCustomer(name, "1 Main St")
+		require.NoError(t, repo.Save(ctx, customers[name]))
+	}
+	require.NoError(t, repo.SetActiveStatus(ctx, customers["SANTOSO, Ayu"].CustomerID, false))
+
+	found, err := repo.FindByNameContaining(ctx, "santoso", 10)
+	require.NoError(t, err)
+	names := make([]string, len(found))
+	for i, c := range found {
+		names[i] = c.Name
+	}
+	assert.Equal(t, []string{"Budi Santoso", "Budi_Santoso"}, names, "inactive customers are left out")
+
+	found, err = repo.FindByNameContaining(ctx, "budi_", 10)
+	require.NoError(t, err)
+	require.Len(t, found, 1, "the underscore is not a wildcard")
+	assert.Equal(t, "Budi_Santoso", found[0].Name)
+}
+
+func TestCustomerRepositorySetDelinquencyStatusBulk(t *testing.T) {
+	repo := NewCustomerRepository(openTestDB(t), clock.System(), testLogger)
+	ctx := context.Background()
+	current := customer.NewCustomer("Alice", "1 Main St")
+	overdue := customer.NewCustomer("Bob", "2 Main St")
+	for _, c := range []*customer.Customer{current, overdue} {
+		require.NoError(t, repo.Save(ctx, c))
+	}
+
+	changed, err := repo.SetDelinquencyStatusBulk(ctx, []customer.CustomerDelinquency{
+		{CustomerID: current.CustomerID, IsDelinquent: false},
+		{CustomerID: overdue.CustomerID, IsDelinquent: true},
+		{CustomerID: overdue.CustomerID + 100, IsDelinquent: true},
+	})
+	require.NoError(t, err)
+	require.Len(t, changed, 1, "unchanged and unknown customers are skipped")
+	assert.Equal(t, overdue.CustomerID, changed[0].CustomerID)
+	assert.True(t, changed[0].IsDelinquent)
+	assert.Equal(t, "Bob", changed[0].Name)
+
+	stored, err := repo.FindByID(ctx, overdue.CustomerID)
+	require.NoError(t, err)
+	assert.True(t, stored.IsDelinquent)
+
+	changed, err = repo.SetDelinquencyStatusBulk(ctx, nil)
+	require.NoError(t, err)
+	assert.Empty(t, changed)
+}
+
+func TestCustomerRepositoryDelinquencyNotices(t *testing.T) {
+	repo := NewCustomerRepository(openTestDB(t), clock.System(), testLogger)
+	ctx := context.Background()
+	ann := customer.NewCustomer("Ann", "1 Main St")
+	bob := customer.NewCustomer("Bob", "2 Main St")
+	for _, c := range []*customer.Customer{ann, bob} {
+		require.NoError(t, repo.Save(ctx, c))
+	}
+	notice := func(c *customer.Customer, bucket string) customer.DelinquencyNotice {
+		return customer.DelinquencyNotice{CustomerID: c.CustomerID, Bucket: bucket}
+	}
+
+	notified, err := repo.MarkDelinquencyNotified(ctx, []customer.DelinquencyNotice{notice(ann, "1-30"), notice(bob, "1-30"), {CustomerID: bob.CustomerID + 100, Bucket: "1-30"}})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []int64{ann.CustomerID, bob.CustomerID}, notified, "unknown customers are skipped")
+
+	notified, err = repo.MarkDelinquencyNotified(ctx, []customer.DelinquencyNotice{notice(ann, "1-30"), notice(bob, "31-60")})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{bob.CustomerID}, notified, "a customer is notified once per bucket")
+
+	require.NoError(t, repo.ClearDelinquencyNotices(ctx, []int64{ann.CustomerID}))
+	notified, err = repo.MarkDelinquencyNotified(ctx, []customer.DelinquencyNotice{notice(ann, "1-30"), notice(bob, "31-60")})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{ann.CustomerID}, notified, "a cleared customer is notified again")
+}
+
+func TestCustomerRepositoryPreferences(t *testing.T) {
+	repo := NewCustomerRepository(openTestDB(t), clock.System(), testLogger)
+	ctx := context.Background()
+
+	cust := customer.NewCustomer("Jane Doe", "1 Main St")
+	require.NoError(t, repo.Save(ctx, cust))
+
+	prefs, err := repo.GetPreferences(ctx, cust.CustomerID)
+	require.NoError(t, err)
+	assert.Equal(t, &customer.Preferences{CustomerID: cust.CustomerID}, prefs)
+
+	want := &customer.Preferences{CustomerID: cust.CustomerID, PreferredChannel: "sms", Language: "id", MarketingOptOut: true}
+	require.NoError(t, repo.SavePreferences(ctx, want))
+	want.PreferredChannel = "email"
+	require.NoError(t, repo.SavePreferences(ctx, want))
+
+	prefs, err = repo.GetPreferences(ctx, cust.CustomerID)
+	require.NoError(t, err)
+	assert.Equal(t, "email", prefs.PreferredChannel)
+	assert.Equal(t, "id", prefs.Language)
+	assert.True(t, prefs.MarketingOptOut)
+	assert.False(t, prefs.TransactionalOptOut)
+	assert.True(t, want.UpdatedAt.Equal(prefs.UpdatedAt))
+
+	_, err = repo.GetPreferences(ctx, 999)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	assert.ErrorIs(t, repo.SavePreferences(ctx, &customer.Preferences{CustomerID: 999}), apperrors.ErrNotFound)
+}
+
+func TestCustomerRepositoryRiskScore(t *testing.T) {
+	repo := NewCustomerRepository(openTestDB(t), clock.System(), testLogger)
+	ctx := context.Background()
+	cust := customer.NewCustomer("Jane Doe", "1 Main St")
+	require.NoError(t, repo.Save(ctx, cust))
+
+	found, err := repo.FindByID(ctx, cust.CustomerID)
+	require.NoError(t, err)
+	assert.False(t, found.Scored())
+	assert.Nil(t, found.RiskScore)
+
+	scoredAt := time.Date(2025, 3, 1, 8, 0, 0, 0, time.FixedZone("WIB", 7*3600))
+	require.NoError(t, repo.SetRiskScore(ctx, &customer.RiskAssessment{CustomerID: cust.CustomerID, Score: 712, Grade: "B+", ScoredAt: scoredAt}))
+
+	found, err = repo.FindByID(ctx, cust.CustomerID)
+	require.NoError(t, err)
+	require.True(t, found.Scored())
+	assert.Equal(t, 712, *found.RiskScore)
+	assert.Equal(t, "B+", *found.RiskGrade)
+	assert.True(t, scoredAt.Equal(*found.RiskScoredAt))
+
+	err = repo.SetRiskScore(ctx, &customer.RiskAssessment{CustomerID: cust.CustomerID + 1, Score: 712, Grade: "B+", ScoredAt: scoredAt})
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+}
@@ -0,0 +1,128 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"database/sql"
+	_ "embed"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+const memoryPath = ":memory:"
+
+var errMsgFormat = "%w: %w"
+
+//go:embed schema.sql
+var schema string
+
+// Open opens the database file at path, creating it and the schema when
+// missing. Path ":memory:" gives a private in-memory database, which lives
+// as long as the returned handle.
+//
+// Write transactions take the database lock when they begin, which stands in
+// for the row locks the PostgreSQL repositories take with FOR UPDATE.
+func Open(ctx context.Context, path string, logger *slog.Logger) (*sql.DB, error) {
+	if path == "" {
+		return nil, fmt.Errorf("SQLite database path is empty in configuration")
+	}
+
+	dsn := "file:" + path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_time_format=sqlite&_txlock=immediate"
+	if path != memoryPath {
+		dsn += "&_pragma=journal_mode(WAL)"
+	}
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open SQLite database: %w", err)
+	}
+	if path == memoryPath {
+		// Every connection to :memory: opens a database of its own.
+		db.SetMaxOpenConns(1)
+	}
+
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to apply SQLite schema: %w", err)
+	}
+
+	logger.Info("Opened SQLite database.", "path", path)
+	return db, nil
+}
+
+// now is the value written to created_at and updated_at. Timestamps are kept
+// in UTC so that they compare correctly as text.
+func now(c clock.Clock) time.Time {
+	return c.Now().UTC()
+}
+
+// dateArg formats a calendar date the way DATE columns store it.
+func dateArg(t time.Time) string {
+	return t.Format(time.DateOnly)
+}
+
+// timeArg normalises an optional timestamp argument to UTC.
+func timeArg(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
+
+// timestampFormats are the layouts the driver writes. Results of aggregates
+// such as MAX have no declared type, so they arrive as text and are parsed
+// here.
+var timestampFormats = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	time.DateOnly,
+}
+
+func parseTimestamp(s string) (time.Time, error) {
+	for _, layout := range timestampFormats {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised SQLite timestamp %q", s)
+}
+
+func sqliteCode(err error) int {
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code()
+	}
+	return 0
+}
+
+func translateDBError(err error, contextLogger *slog.Logger) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return apperrors.ErrNotFound
+	}
+	switch code := sqliteCode(err); code {
+	case 0:
+	case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+		contextLogger.Warn("Database unique constraint violation", "error", err)
+		return fmt.Errorf("%w: %w", apperrors.ErrAlreadyExists, err)
+	default:
+		contextLogger.Error("SQLite specific error", "code", code, "error", err)
+		return fmt.Errorf("%w: db error code %d", apperrors.ErrDatabase, code)
+	}
+
+	contextLogger.Error("Generic database error", "error", err)
+	return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+}
+
+// rowScanner is satisfied by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
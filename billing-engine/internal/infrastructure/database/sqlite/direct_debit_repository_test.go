@@ -0,0 +1,100 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"billing-engine/internal/domain/directdebit"
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirectDebitRepository(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewDirectDebitRepository(db, clock.System(), testLogger)
+	ctx := context.Background()
+	customerID, created := createTestLoan(t, db, day("2025-01-06"), "")
+
+	mandate := &directdebit.Mandate{
+		CustomerID: customerID, Reference: "MNDT-1", AccountHolder: "Jane Doe", AccountNumber: "9876543210",
+		BankCode: "BANKIDJB", Status: directdebit.MandateActive, SignedAt: day("2025-01-02"),
+	}
+	require.NoError(t, repo.CreateMandate(ctx, mandate))
+	second := *mandate
+	second.Reference = "MNDT-2"
+	assert.ErrorIs(t, repo.CreateMandate(ctx, &second), apperrors.ErrConflict)
+	second.CustomerID = customerID + 100
+	assert.ErrorIs(t, repo.CreateMandate(ctx, &second), apperrors.ErrNotFound)
+
+	mandates, err := repo.ListMandates(ctx, customerID)
+	require.NoError(t, err)
+	require.Len(t, mandates, 1)
+	assert.Equal(t, day("2025-01-02"), mandates[0].SignedAt)
+
+	installments, err := repo.ListUncoveredInstallments(ctx, day("2025-01-20"))
+	require.NoError(t, err)
+	require.Len(t, installments, 2, "the third week is due after the horizon")
+	assert.Equal(t, created.ID, installments[0].LoanID)
+	assert.Equal(t, "MNDT-1", installments[0].Mandate.Reference)
+	assert.Equal(t, 110.0, installments[0].Amount)
+
+	first := &directdebit.Instruction{
+		MandateID: mandate.ID, LoanID: created.ID, ScheduleID: installments[0].ScheduleID, WeekNumber: 1,
+		EndToEndID: "E2E1", Amount: 110, CollectionDate: day("2025-01-13"), Status: directdebit.InstructionPending,
+	}
+	require.NoError(t, repo.CreateInstruction(ctx, first))
+	again := *first
+	again.EndToEndID = "E2E2"
+	assert.ErrorIs(t, repo.CreateInstruction(ctx, &again), apperrors.ErrAlreadyExists)
+
+	installments, err = repo.ListUncoveredInstallments(ctx, day("2025-01-20"))
+	require.NoError(t, err)
+	assert.Len(t, installments, 1)
+
+	pending, err := repo.ListInstructions(ctx, directdebit.InstructionPending)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, day("2025-01-13"), pending[0].CollectionDate)
+	assert.Equal(t, "9876543210", pending[0].Mandate.AccountNumber)
+
+	n, err := repo.MarkExported(ctx, []int64{first.ID}, "DD-1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	n, err = repo.MarkExported(ctx, []int64{first.ID}, "DD-2")
+	require.NoError(t, err)
+	assert.Zero(t, n, "exported instructions are not exported again")
+
+	got, err := repo.GetInstructionByEndToEndID(ctx, "E2E1")
+	require.NoError(t, err)
+	assert.Equal(t, directdebit.InstructionExported, got.Status)
+	assert.Equal(t, "DD-1", got.BatchRef)
+
+	require.NoError(t, repo.TransitionInstruction(ctx, first.ID, directdebit.InstructionExported, directdebit.InstructionFailed, "AM04"))
+	assert.ErrorIs(t, repo.TransitionInstruction(ctx, first.ID, directdebit.InstructionExported, directdebit.InstructionCollected, ""), apperrors.ErrConflict)
+	got, err = repo.GetInstructionByEndToEndID(ctx, "E2E1")
+	require.NoError(t, err)
+	assert.Equal(t, "AM04", got.FailureReason)
+
+	installments, err = repo.ListUncoveredInstallments(ctx, day("2025-01-20"))
+	require.NoError(t, err)
+	assert.Len(t, installments, 2, "a failed collection is attempted again")
+
+	loans := NewLoanRepository(db, clock.System(), testLogger)
+	require.NoError(t, loans.PlaceHold(ctx, &loan.Hold{LoanID: created.ID, Reason: "disputed", PlacedAt: day("2025-01-14")}))
+	installments, err = repo.ListUncoveredInstallments(ctx, day("2025-01-20"))
+	require.NoError(t, err)
+	assert.Empty(t, installments, "loans on hold are not collected")
+	_, err = loans.ReleaseHold(ctx, created.ID, nil, day("2025-01-15"))
+	require.NoError(t, err)
+
+	require.NoError(t, repo.CancelMandate(ctx, customerID, mandate.ID))
+	assert.ErrorIs(t, repo.CancelMandate(ctx, customerID, mandate.ID), apperrors.ErrNotFound)
+	installments, err = repo.ListUncoveredInstallments(ctx, day("2025-01-20"))
+	require.NoError(t, err)
+	assert.Empty(t, installments)
+}
@@ -0,0 +1,10 @@
+// Package sqlite stores loans, customers and notes in a single SQLite file
+// so the service can run locally without PostgreSQL. It implements the same
+// domain repository interfaces as package postgres and is selected with
+// database.driver "sqlite".
+//
+// The implementation is only compiled with the sqlite build tag, which keeps
+// the driver out of production binaries:
+//
+//	go run -tags sqlite ./cmd
+package sqlite
@@ -0,0 +1,228 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"billing-engine/internal/domain/document"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+const documentColumns = `id, loan_id, customer_id, type, status, file_name, content_type, size_bytes, storage_key, COALESCE(uploaded_by, ''), created_at`
+
+type DocumentRepository struct {
+	db     *sql.DB
+	clock  clock.Clock
+	logger *slog.Logger
+}
+
+var _ document.Repository = (*DocumentRepository)(nil)
+
+func NewDocumentRepository(db *sql.DB, clk clock.Clock, logger *slog.Logger) *DocumentRepository {
+	return &DocumentRepository{db: db, clock: clock.OrSystem(clk), logger: logger.With("component", "DocumentRepository")}
+}
+
+func (r *DocumentRepository) ListUncertified(ctx context.Context, since time.Time, limit int) ([]document.PaidOffLoan, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT l.id, l.public_id, COALESCE(l.external_ref, ''), c.id, COALESCE(c.name, ''),
+               l.principal_amount, l.total_loan_amount, l.start_date, COALESCE(MAX(s.payment_date), l.updated_at)
+        FROM loans l
+        LEFT JOIN customers c ON c.loan_id = l.id
+        LEFT JOIN loan_schedule s ON s.loan_id = l.id
+        WHERE l.status = 'PAID_OFF'
+          AND NOT EXISTS (SELECT 1 FROM documents d WHERE d.loan_id = l.id AND d.type = 'PAYOFF_CERTIFICATE')
+        GROUP BY l.id, c.id
+        HAVING COALESCE(MAX(s.payment_date), l.updated_at) >= $1
+        ORDER BY COALESCE(MAX(s.payment_date), l.updated_at), l.id
+        LIMIT $2`, since.UTC(), limit)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query loans awaiting a payoff certificate", slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to list loans awaiting a payoff certificate: %w", apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	loans := []document.PaidOffLoan{}
+	for rows.Next() {
+		var l document.PaidOffLoan
+		var paidOffAt string
+		if err := rows.Scan(&l.LoanID, &l.PublicID, &l.ExternalRef, &l.CustomerID, &l.CustomerName,
+			&l.Principal, &l.TotalRepaid, &l.StartDate, &paidOffAt); err != nil {
+			return nil, fmt.Errorf("%w: failed to scan paid-off loan: %w", apperrors.ErrDatabase, err)
+		}
+		if l.PaidOffAt, err = parseTimestamp(paidOffAt); err != nil {
+			return nil, fmt.Errorf("%w: %w", apperrors.ErrDatabase, err)
+		}
+		loans = append(loans, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: failed to iterate paid-off loans: %w", apperrors.ErrDatabase, err)
+	}
+	return loans, nil
+}
+
+// documentSubjectColumn returns the column that references subject and
+// the table it references.
+func documentSubjectColumn(subject document.Subject) (column, table string, err error) {
+	switch subject.Type {
+	case document.SubjectLoan:
+		return "loan_id", "loans", nil
+	case document.SubjectCustomer:
+		return "customer_id", "customers", nil
+	}
+	return "", "", fmt.Errorf("%w: unknown subject type %q", apperrors.ErrInvalidArgument, subject.Type)
+}
+
+func (r *DocumentRepository) Create(ctx context.Context, d *document.Document) error {
+	column, _, err := documentSubjectColumn(d.Subject)
+	if err != nil {
+		return err
+	}
+	createdAt := now(r.clock)
+	err = r.db.QueryRowContext(ctx, fmt.Sprintf(`
+        INSERT INTO documents (%s, type, status, file_name, content_type, size_bytes, storage_key, uploaded_by, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), $9)
+        RETURNING id`, column), d.Subject.ID, d.Type, d.Status, d.FileName, d.ContentType, d.SizeBytes, d.StorageKey, d.UploadedBy, createdAt).Scan(&d.ID)
+	switch code := sqliteCode(err); {
+	case err == nil:
+		d.CreatedAt = createdAt
+		return nil
+	case code == sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
+		return fmt.Errorf("%w: %s", apperrors.ErrNotFound, d.Subject)
+	case code == sqlite3.SQLITE_CONSTRAINT_UNIQUE:
+		return fmt.Errorf("%w: %s already has a %s", apperrors.ErrConflict, d.Subject, d.Type)
+	}
+	r.logger.ErrorContext(ctx, "Failed to insert document", slog.String("subject", d.Subject.String()), slog.Any("error", err))
+	return fmt.Errorf("%w: failed to insert document: %w", apperrors.ErrDatabase, err)
+}
+
+func (r *DocumentRepository) List(ctx context.Context, subject document.Subject) ([]document.Document, error) {
+	column, _, err := documentSubjectColumn(subject)
+	if err != nil {
+		return nil, err
+	}
+	return r.queryDocuments(ctx, fmt.Sprintf(`SELECT %s FROM documents WHERE %s = $1 ORDER BY created_at DESC, id DESC`, documentColumns, column), subject.ID)
+}
+
+func (r *DocumentRepository) Get(ctx context.Context, subject document.Subject, documentID int64) (*document.Document, error) {
+	column, _, err := documentSubjectColumn(subject)
+	if err != nil {
+		return nil, err
+	}
+	var d document.Document
+	row := r.db.QueryRowContext(ctx, fmt.Sprintf(`SELECT %s FROM documents WHERE id = $1 AND %s = $2`, documentColumns, column), documentID, subject.ID)
+	if err := scanDocument(row, &d); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: document %d of %s", apperrors.ErrNotFound, documentID, subject)
+		}
+		r.logger.ErrorContext(ctx, "Failed to get document", slog.Int64("documentID", documentID), slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to get document: %w", apperrors.ErrDatabase, err)
+	}
+	return &d, nil
+}
+
+func (r *DocumentRepository) MarkAvailable(ctx context.Context, subject document.Subject, documentID int64) error {
+	column, _, err := documentSubjectColumn(subject)
+	if err != nil {
+		return err
+	}
+	res, err := r.db.ExecContext(ctx, fmt.Sprintf(`UPDATE documents SET status = 'AVAILABLE' WHERE id = $1 AND %s = $2 AND status = 'PENDING'`, column), documentID, subject.ID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to mark document available", slog.Int64("documentID", documentID), slog.Any("error", err))
+		return fmt.Errorf("%w: failed to update document: %w", apperrors.ErrDatabase, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: pending document %d of %s", apperrors.ErrNotFound, documentID, subject)
+	}
+	return nil
+}
+
+func (r *DocumentRepository) Delete(ctx context.Context, subject document.Subject, documentID int64) error {
+	column, _, err := documentSubjectColumn(subject)
+	if err != nil {
+		return err
+	}
+	res, err := r.db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM documents WHERE id = $1 AND %s = $2`, column), documentID, subject.ID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to delete document", slog.Int64("documentID", documentID), slog.Any("error", err))
+		return fmt.Errorf("%w: failed to delete document: %w", apperrors.ErrDatabase, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: document %d of %s", apperrors.ErrNotFound, documentID, subject)
+	}
+	return nil
+}
+
+func (r *DocumentRepository) ListExpired(ctx context.Context, t document.Type, before time.Time, limit int) ([]document.Document, error) {
+	return r.queryDocuments(ctx, `
+        SELECT `+documentColumns+` FROM documents d
+        WHERE d.type = $1 AND d.status = 'AVAILABLE' AND d.created_at < $2
+          AND NOT EXISTS (SELECT 1 FROM loan_holds h WHERE h.loan_id = d.loan_id AND h.released_at IS NULL)
+        ORDER BY d.created_at, d.id
+        LIMIT $3`, t, before.UTC(), limit)
+}
+
+func (r *DocumentRepository) ListStalePending(ctx context.Context, before time.Time, limit int) ([]document.Document, error) {
+	return r.queryDocuments(ctx, `
+        SELECT `+documentColumns+` FROM documents
+        WHERE status = 'PENDING' AND created_at < $1
+        ORDER BY created_at, id
+        LIMIT $2`, before.UTC(), limit)
+}
+
+func (r *DocumentRepository) SubjectExists(ctx context.Context, subject document.Subject) (bool, error) {
+	_, table, err := documentSubjectColumn(subject)
+	if err != nil {
+		return false, err
+	}
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM %s WHERE id = $1)`, table), subject.ID).Scan(&exists); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to check subject", slog.String("subject", subject.String()), slog.Any("error", err))
+		return false, fmt.Errorf("%w: failed to check %s: %w", apperrors.ErrDatabase, subject.Type, err)
+	}
+	return exists, nil
+}
+
+func (r *DocumentRepository) queryDocuments(ctx context.Context, query string, args ...any) ([]document.Document, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query documents", slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to list documents: %w", apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	documents := []document.Document{}
+	for rows.Next() {
+		var d document.Document
+		if err := scanDocument(rows, &d); err != nil {
+			return nil, fmt.Errorf("%w: failed to scan document: %w", apperrors.ErrDatabase, err)
+		}
+		documents = append(documents, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: failed to iterate documents: %w", apperrors.ErrDatabase, err)
+	}
+	return documents, nil
+}
+
+func scanDocument(row rowScanner, d *document.Document) error {
+	var loanID, customerID *int64
+	if err := row.Scan(&d.ID, &loanID, &customerID, &d.Type, &d.Status, &d.FileName, &d.ContentType, &d.SizeBytes, &d.StorageKey, &d.UploadedBy, &d.CreatedAt); err != nil {
+		return err
+	}
+	switch {
+	case loanID != nil:
+		d.Subject = document.LoanSubject(*loanID)
+	case customerID != nil:
+		d.Subject = document.Subject{Type: document.SubjectCustomer, ID: *customerID}
+	}
+	return nil
+}
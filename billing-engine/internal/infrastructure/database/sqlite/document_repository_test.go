@@ -0,0 +1,134 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"billing-engine/internal/domain/document"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDocumentRepository(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewDocumentRepository(db, clock.System(), testLogger)
+	ctx := context.Background()
+
+	customerID, paidOff := createTestLoan(t, db, day("2025-01-06"), "ref-paid")
+	_, old := createTestLoan(t, db, day("2023-01-02"), "ref-old")
+	createTestLoan(t, db, day("2025-01-06"), "ref-active")
+	paidAt := time.Date(2025, 1, 27, 10, 0, 0, 0, time.UTC)
+	for loanID, at := range map[int64]time.Time{paidOff.ID: paidAt, old.ID: day("2023-01-23")} {
+		_, err := db.ExecContext(ctx, `UPDATE loan_schedule SET status = 'PAID', paid_amount = due_amount, payment_date = $1 WHERE loan_id = $2`, at, loanID)
+		require.NoError(t, err)
+		_, err = db.ExecContext(ctx, `UPDATE loans SET status = 'PAID_OFF' WHERE id = $1`, loanID)
+		require.NoError(t, err)
+	}
+
+	loans, err := repo.ListUncertified(ctx, day("2025-01-01"), 10)
+	require.NoError(t, err)
+	require.Len(t, loans, 1, "only the recently paid-off loan qualifies")
+	assert.Equal(t, paidOff.ID, loans[0].LoanID)
+	assert.Equal(t, paidOff.PublicID, loans[0].PublicID)
+	assert.Equal(t, "ref-paid", loans[0].ExternalRef)
+	assert.Equal(t, &customerID, loans[0].CustomerID)
+	assert.True(t, paidAt.Equal(loans[0].PaidOffAt))
+
+	documents, err := repo.List(ctx, document.LoanSubject(paidOff.ID))
+	require.NoError(t, err)
+	assert.Empty(t, documents)
+
+	d := &document.Document{Subject: document.LoanSubject(paidOff.ID), Type: document.TypePayoffCertificate, Status: document.StatusAvailable,
+		FileName: "payoff-certificate.pdf", ContentType: document.ContentTypePDF, SizeBytes: 1024, StorageKey: "loans/1/documents/key.pdf"}
+	require.NoError(t, repo.Create(ctx, d))
+	assert.NotZero(t, d.ID)
+	err = repo.Create(ctx, &document.Document{Subject: document.LoanSubject(paidOff.ID), Type: document.TypePayoffCertificate, Status: document.StatusAvailable,
+		FileName: "again.pdf", ContentType: document.ContentTypePDF, SizeBytes: 1, StorageKey: "k"})
+	assert.ErrorIs(t, err, apperrors.ErrConflict)
+	err = repo.Create(ctx, &document.Document{Subject: document.LoanSubject(999), Type: document.TypePayoffCertificate, Status: document.StatusAvailable,
+		FileName: "f.pdf", ContentType: document.ContentTypePDF, SizeBytes: 1, StorageKey: "k"})
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+
+	loans, err = repo.ListUncertified(ctx, day("2025-01-01"), 10)
+	require.NoError(t, err)
+	assert.Empty(t, loans, "a certified loan is not listed again")
+
+	documents, err = repo.List(ctx, document.LoanSubject(paidOff.ID))
+	require.NoError(t, err)
+	require.Len(t, documents, 1)
+	assert.Equal(t, d.StorageKey, documents[0].StorageKey)
+
+	got, err := repo.Get(ctx, document.LoanSubject(paidOff.ID), d.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1024), got.SizeBytes)
+	assert.Equal(t, document.StatusAvailable, got.Status)
+	_, err = repo.Get(ctx, document.LoanSubject(old.ID), d.ID)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+
+	customer := document.Subject{Type: document.SubjectCustomer, ID: customerID}
+	exists, err := repo.SubjectExists(ctx, customer)
+	require.NoError(t, err)
+	assert.True(t, exists)
+	exists, err = repo.SubjectExists(ctx, document.LoanSubject(999))
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestDocumentRepositoryUploads(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewDocumentRepository(db, clock.System(), testLogger)
+	ctx := context.Background()
+
+	customerID, l := createTestLoan(t, db, day("2025-01-06"), "ref-upload")
+	customer := document.Subject{Type: document.SubjectCustomer, ID: customerID}
+
+	idCopy := &document.Document{Subject: customer, Type: document.TypeIDCopy, Status: document.StatusPending,
+		FileName: "ktp.png", ContentType: "image/png", SizeBytes: 2048, StorageKey: "customers/1/documents/a.png", UploadedBy: "agent"}
+	require.NoError(t, repo.Create(ctx, idCopy))
+	contract := &document.Document{Subject: document.LoanSubject(l.ID), Type: document.TypeContract, Status: document.StatusPending,
+		FileName: "contract.pdf", ContentType: document.ContentTypePDF, SizeBytes: 4096, StorageKey: "loans/1/documents/b.pdf"}
+	require.NoError(t, repo.Create(ctx, contract))
+	err := repo.Create(ctx, &document.Document{Subject: document.Subject{Type: document.SubjectCustomer, ID: 999}, Type: document.TypeIDCopy,
+		Status: document.StatusPending, FileName: "f.png", ContentType: "image/png", SizeBytes: 1, StorageKey: "customers/999/documents/c.png"})
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+
+	documents, err := repo.List(ctx, customer)
+	require.NoError(t, err)
+	require.Len(t, documents, 1, "the loan's contract is not listed on the customer")
+	assert.Equal(t, customer, documents[0].Subject)
+	assert.Equal(t, "agent", documents[0].UploadedBy)
+
+	later := time.Now().Add(time.Hour)
+	pending, err := repo.ListStalePending(ctx, later, 10)
+	require.NoError(t, err)
+	assert.Len(t, pending, 2)
+	expired, err := repo.ListExpired(ctx, document.TypeContract, later, 10)
+	require.NoError(t, err)
+	assert.Empty(t, expired, "a pending upload does not expire")
+
+	require.NoError(t, repo.MarkAvailable(ctx, contract.Subject, contract.ID))
+	assert.ErrorIs(t, repo.MarkAvailable(ctx, contract.Subject, contract.ID), apperrors.ErrNotFound, "only a pending document is marked available")
+
+	expired, err = repo.ListExpired(ctx, document.TypeContract, later, 10)
+	require.NoError(t, err)
+	require.Len(t, expired, 1)
+	assert.Equal(t, contract.ID, expired[0].ID)
+	expired, err = repo.ListExpired(ctx, document.TypeContract, time.Now().Add(-time.Hour), 10)
+	require.NoError(t, err)
+	assert.Empty(t, expired, "a document younger than the cutoff is kept")
+
+	_, err = db.ExecContext(ctx, `INSERT INTO loan_holds (loan_id, reason, placed_at) VALUES ($1, 'litigation', $2)`, l.ID, time.Now().UTC())
+	require.NoError(t, err)
+	expired, err = repo.ListExpired(ctx, document.TypeContract, later, 10)
+	require.NoError(t, err)
+	assert.Empty(t, expired, "a loan under hold keeps its documents")
+
+	require.NoError(t, repo.Delete(ctx, customer, idCopy.ID))
+	assert.ErrorIs(t, repo.Delete(ctx, customer, idCopy.ID), apperrors.ErrNotFound)
+	assert.ErrorIs(t, repo.Delete(ctx, customer, contract.ID), apperrors.ErrNotFound, "a document is deleted only through its own subject")
+}
@@ -0,0 +1,163 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"billing-engine/internal/event"
+	"billing-engine/internal/pkg/apperrors"
+
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+type EventLogRepository struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+var _ event.Store = (*EventLogRepository)(nil)
+
+func NewEventLogRepository(db *sql.DB, logger *slog.Logger) *EventLogRepository {
+	return &EventLogRepository{db: db, logger: logger.With("component", "EventLogRepository")}
+}
+
+func (r *EventLogRepository) Append(ctx context.Context, rec *event.Record) error {
+	err := r.db.QueryRowContext(ctx, appendEventQuery,
+		rec.EventID, rec.Type, rec.EntityID, rec.Sequence, string(rec.Payload), rec.OccurredAt.UTC(),
+	).Scan(&rec.ID)
+	if err != nil {
+		return r.appendError(ctx, rec, err)
+	}
+	return nil
+}
+
+// AppendNumbered numbers and logs the event in one transaction; SQLite lets
+// a single writer through at a time.
+func (r *EventLogRepository) AppendNumbered(ctx context.Context, rec *event.Record, encode func(seq int64) (json.RawMessage, error)) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%w: failed to begin transaction: %w", apperrors.ErrDatabase, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var seq int64
+	err = tx.QueryRowContext(ctx, `
+        INSERT INTO event_sequences (entity_id, last_sequence) VALUES ($1, 1)
+        ON CONFLICT (entity_id) DO UPDATE SET last_sequence = last_sequence + 1
+        RETURNING last_sequence`, rec.EntityID).Scan(&seq)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to number event", slog.Int64("entityId", rec.EntityID), slog.Any("error", err))
+		return fmt.Errorf("%w: failed to number event: %w", apperrors.ErrDatabase, err)
+	}
+	payload, err := encode(seq)
+	if err != nil {
+		return fmt.Errorf("failed to encode event %s: %w", rec.EventID, err)
+	}
+	var id int64
+	err = tx.QueryRowContext(ctx, appendEventQuery,
+		rec.EventID, rec.Type, rec.EntityID, seq, string(payload), rec.OccurredAt.UTC(),
+	).Scan(&id)
+	if err != nil {
+		return r.appendError(ctx, rec, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%w: failed to commit event: %w", apperrors.ErrDatabase, err)
+	}
+	rec.ID, rec.Sequence, rec.Payload = id, seq, payload
+	return nil
+}
+
+const appendEventQuery = `
+        INSERT INTO event_log (event_id, event_type, entity_id, sequence, payload, occurred_at)
+        VALUES ($1, $2, $3, NULLIF($4, 0), $5, $6)
+        RETURNING id`
+
+func (r *EventLogRepository) appendError(ctx context.Context, rec *event.Record, err error) error {
+	if sqliteCode(err) == sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+		return fmt.Errorf("%w: event %s is already recorded", apperrors.ErrAlreadyExists, rec.EventID)
+	}
+	r.logger.ErrorContext(ctx, "Failed to record event", slog.String("eventId", rec.EventID), slog.Any("error", err))
+	return fmt.Errorf("%w: failed to record event: %w", apperrors.ErrDatabase, err)
+}
+
+func (r *EventLogRepository) MarkPublished(ctx context.Context, eventID string, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE event_log SET published_at = $2 WHERE event_id = $1 AND published_at IS NULL`, eventID, at.UTC())
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to mark event published", slog.String("eventId", eventID), slog.Any("error", err))
+		return fmt.Errorf("%w: failed to mark event published: %w", apperrors.ErrDatabase, err)
+	}
+	return nil
+}
+
+// List passes the types as a JSON array because SQLite has no array
+// parameters.
+func (r *EventLogRepository) List(ctx context.Context, f event.ReplayFilter) ([]event.Record, error) {
+	types := f.Types
+	if types == nil {
+		types = []string{}
+	}
+	typesJSON, err := json.Marshal(types)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", apperrors.ErrInternalServer, err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT id, event_id, event_type, entity_id, COALESCE(sequence, 0), payload, occurred_at, published_at, replay_count, last_replayed_at
+        FROM event_log
+        WHERE ($1 IS NULL OR entity_id = $1)
+          AND ($2 = '[]' OR event_type IN (SELECT value FROM json_each($2)))
+          AND ($3 IS NULL OR occurred_at >= $3)
+          AND ($4 IS NULL OR occurred_at < $4)
+          AND (NOT $5 OR published_at IS NULL)
+        ORDER BY id
+        LIMIT $6`,
+		f.EntityID, string(typesJSON), timeArg(f.Since), timeArg(f.Until), f.UnpublishedOnly, f.Limit)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query event log", slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to list events: %w", apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	records := []event.Record{}
+	for rows.Next() {
+		var rec event.Record
+		var payload string
+		if err := rows.Scan(&rec.ID, &rec.EventID, &rec.Type, &rec.EntityID, &rec.Sequence, &payload, &rec.OccurredAt, &rec.PublishedAt,
+			&rec.ReplayCount, &rec.LastReplayedAt); err != nil {
+			return nil, fmt.Errorf("%w: failed to scan event: %w", apperrors.ErrDatabase, err)
+		}
+		rec.Payload = json.RawMessage(payload)
+		records = append(records, rec)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: failed to iterate events: %w", apperrors.ErrDatabase, err)
+	}
+	return records, nil
+}
+
+func (r *EventLogRepository) MarkReplayed(ctx context.Context, id int64, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+        UPDATE event_log
+        SET replay_count = replay_count + 1, last_replayed_at = $2, published_at = COALESCE(published_at, $2)
+        WHERE id = $1`, id, at.UTC())
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to record event replay", slog.Int64("id", id), slog.Any("error", err))
+		return fmt.Errorf("%w: failed to record event replay: %w", apperrors.ErrDatabase, err)
+	}
+	return nil
+}
+
+func (r *EventLogRepository) CountUnpublished(ctx context.Context) (int64, error) {
+	var n int64
+	if err := r.db.QueryRowContext(ctx, `SELECT count(*) FROM event_log WHERE published_at IS NULL`).Scan(&n); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to count unpublished events", slog.Any("error", err))
+		return 0, fmt.Errorf("%w: failed to count unpublished events: %w", apperrors.ErrDatabase, err)
+	}
+	return n, nil
+}
@@ -0,0 +1,89 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"billing-engine/internal/event"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventLogRepository(t *testing.T) {
+	repo := NewEventLogRepository(openTestDB(t), testLogger)
+	ctx := context.Background()
+	raisedAt := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+
+	for i, rec := range []event.Record{
+		{EventID: "e-1", Type: event.TypeCustomerCreated, EntityID: 7},
+		{EventID: "e-2", Type: event.TypeCustomerUpdated, EntityID: 8},
+	} {
+		rec.OccurredAt = raisedAt.Add(time.Duration(i) * time.Hour)
+		require.NoError(t, repo.AppendNumbered(ctx, &rec, func(seq int64) (json.RawMessage, error) {
+			return json.RawMessage(`{"eventId": "` + rec.EventID + `"}`), nil
+		}))
+		assert.Equal(t, int64(i+1), rec.ID)
+		assert.Equal(t, int64(1), rec.Sequence, "numbered per customer")
+	}
+	unnumbered := event.Record{EventID: "e-3", Type: event.TypeCustomerDelinquencyChanged, EntityID: 7,
+		Payload: json.RawMessage(`{"eventId": "e-3"}`), OccurredAt: raisedAt.Add(2 * time.Hour)}
+	require.NoError(t, repo.Append(ctx, &unnumbered))
+	assert.Equal(t, int64(3), unnumbered.ID)
+
+	dup := event.Record{EventID: "e-1", Type: event.TypeCustomerCreated, Payload: json.RawMessage(`{}`), OccurredAt: raisedAt}
+	assert.ErrorIs(t, repo.Append(ctx, &dup), apperrors.ErrAlreadyExists)
+
+	publishedAt := raisedAt.Add(time.Minute)
+	require.NoError(t, repo.MarkPublished(ctx, "e-1", publishedAt))
+
+	entity := int64(7)
+	records, err := repo.List(ctx, event.ReplayFilter{EntityID: &entity, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "e-1", records[0].EventID)
+	assert.Equal(t, json.RawMessage(`{"eventId": "e-1"}`), records[0].Payload, "payload is kept byte for byte")
+	assert.True(t, records[0].PublishedAt.Equal(publishedAt))
+	assert.Nil(t, records[1].PublishedAt)
+	assert.Equal(t, []int64{1, 0}, []int64{records[0].Sequence, records[1].Sequence}, "an unnumbered event reads as zero")
+
+	until := raisedAt.Add(2 * time.Hour)
+	records, err = repo.List(ctx, event.ReplayFilter{Types: []string{event.TypeCustomerCreated, event.TypeCustomerUpdated}, Until: &until, Limit: 10})
+	require.NoError(t, err)
+	assert.Len(t, records, 2)
+
+	since := raisedAt.Add(time.Hour)
+	records, err = repo.List(ctx, event.ReplayFilter{Since: &since, UnpublishedOnly: true, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "e-2", records[0].EventID)
+
+	unpublished, err := repo.CountUnpublished(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), unpublished)
+
+	replayedAt := raisedAt.Add(24 * time.Hour)
+	require.NoError(t, repo.MarkReplayed(ctx, 3, replayedAt))
+	records, err = repo.List(ctx, event.ReplayFilter{EntityID: &entity, UnpublishedOnly: true, Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, records, "a replay publishes the event")
+	records, err = repo.List(ctx, event.ReplayFilter{Types: []string{event.TypeCustomerDelinquencyChanged}, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, 1, records[0].ReplayCount)
+	assert.True(t, records[0].LastReplayedAt.Equal(replayedAt))
+
+	retry := event.Record{EventID: "e-1", Type: event.TypeCustomerCreated, EntityID: 7, OccurredAt: raisedAt}
+	assert.ErrorIs(t, repo.AppendNumbered(ctx, &retry, func(seq int64) (json.RawMessage, error) {
+		return json.RawMessage(`{}`), nil
+	}), apperrors.ErrAlreadyExists)
+	next := event.Record{EventID: "e-4", Type: event.TypeCustomerUpdated, EntityID: 7, OccurredAt: raisedAt.Add(3 * time.Hour)}
+	require.NoError(t, repo.AppendNumbered(ctx, &next, func(seq int64) (json.RawMessage, error) {
+		return json.RawMessage(`{"eventId": "e-4"}`), nil
+	}))
+	assert.Equal(t, int64(2), next.Sequence, "the failed append gave its number back")
+}
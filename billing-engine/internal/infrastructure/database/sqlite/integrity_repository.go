@@ -0,0 +1,164 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"billing-engine/internal/domain/integrity"
+	"billing-engine/internal/pkg/apperrors"
+)
+
+// integrityDetector is the query of a check and how a row of it becomes a
+// finding. The queries are those of the PostgreSQL repository.
+type integrityDetector struct {
+	query string
+	scan  func(rows *sql.Rows) (integrity.Finding, error)
+}
+
+var integrityDetectors = map[integrity.Check]integrityDetector{
+	integrity.CheckCustomerLoanMissing: {`
+        SELECT c.id, c.loan_id
+        FROM customers c
+        LEFT JOIN loans l ON l.id = c.loan_id
+        WHERE c.loan_id IS NOT NULL AND l.id IS NULL
+        ORDER BY c.id`, func(rows *sql.Rows) (integrity.Finding, error) {
+		var customerID, loanID int64
+		err := rows.Scan(&customerID, &loanID)
+		return integrity.CustomerLoanMissing(customerID, loanID), err
+	}},
+	integrity.CheckLedgerMismatch: {`
+        SELECT s.loan_id, s.paid, COALESCE(p.paid, 0)
+        FROM (SELECT loan_id, SUM(paid_amount) AS paid FROM loan_schedule GROUP BY loan_id) s
+        LEFT JOIN (SELECT loan_id, SUM(amount) AS paid FROM payments WHERE schedule_id IS NOT NULL GROUP BY loan_id) p ON p.loan_id = s.loan_id
+        WHERE ABS(s.paid - COALESCE(p.paid, 0)) > 0.005
+        ORDER BY s.loan_id`, func(rows *sql.Rows) (integrity.Finding, error) {
+		var loanID int64
+		var schedulePaid, ledgerPaid float64
+		err := rows.Scan(&loanID, &schedulePaid, &ledgerPaid)
+		return integrity.LedgerMismatch(loanID, schedulePaid, ledgerPaid), err
+	}},
+	integrity.CheckPaidOffUnpaid: {`
+        SELECT l.id, COUNT(*)
+        FROM loans l
+        JOIN loan_schedule s ON s.loan_id = l.id
+        WHERE l.status = 'PAID_OFF' AND s.status != 'PAID'
+        GROUP BY l.id
+        ORDER BY l.id`, func(rows *sql.Rows) (integrity.Finding, error) {
+		var loanID int64
+		var unpaid int
+		err := rows.Scan(&loanID, &unpaid)
+		return integrity.PaidOffUnpaid(loanID, unpaid), err
+	}},
+	integrity.CheckOrphanSchedule: {`
+        SELECT s.loan_id, COUNT(*)
+        FROM loan_schedule s
+        LEFT JOIN loans l ON l.id = s.loan_id
+        WHERE l.id IS NULL
+        GROUP BY s.loan_id
+        ORDER BY s.loan_id`, func(rows *sql.Rows) (integrity.Finding, error) {
+		var loanID int64
+		var entries int
+		err := rows.Scan(&loanID, &entries)
+		return integrity.OrphanSchedule(loanID, entries), err
+	}},
+}
+
+type IntegrityRepository struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+var _ integrity.Repository = (*IntegrityRepository)(nil)
+
+func NewIntegrityRepository(db *sql.DB, logger *slog.Logger) *IntegrityRepository {
+	return &IntegrityRepository{db: db, logger: logger.With("component", "IntegrityRepository")}
+}
+
+func (r *IntegrityRepository) Detect(ctx context.Context, check integrity.Check) ([]integrity.Finding, error) {
+	detector, ok := integrityDetectors[check]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown integrity check %q", apperrors.ErrInvalidArgument, check)
+	}
+
+	rows, err := r.db.QueryContext(ctx, detector.query)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to run integrity check", slog.String("check", string(check)), slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to run integrity check %s: %w", apperrors.ErrDatabase, check, err)
+	}
+	defer rows.Close()
+
+	findings := []integrity.Finding{}
+	for rows.Next() {
+		f, err := detector.scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to scan integrity check %s: %w", apperrors.ErrDatabase, check, err)
+		}
+		findings = append(findings, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: failed to iterate integrity check %s: %w", apperrors.ErrDatabase, check, err)
+	}
+	return findings, nil
+}
+
+func (r *IntegrityRepository) ReplaceFindings(ctx context.Context, runAt time.Time, findings []integrity.Finding) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to begin transaction", slog.Any("error", err))
+		return fmt.Errorf("%w: failed to begin transaction: %w", apperrors.ErrDatabase, err)
+	}
+	defer tx.Rollback()
+
+	seenAt := runAt.UTC()
+	for _, f := range findings {
+		_, err := tx.ExecContext(ctx, `
+            INSERT INTO integrity_findings (check_name, entity_type, entity_id, detail, first_seen_at, last_seen_at)
+            VALUES ($1, $2, $3, $4, $5, $5)
+            ON CONFLICT (check_name, entity_id) DO UPDATE SET
+                entity_type = excluded.entity_type, detail = excluded.detail, last_seen_at = excluded.last_seen_at`,
+			f.Check, f.EntityType, f.EntityID, f.Detail, seenAt)
+		if err != nil {
+			r.logger.ErrorContext(ctx, "Failed to store integrity finding", slog.String("check", string(f.Check)), slog.Int64("entityID", f.EntityID), slog.Any("error", err))
+			return fmt.Errorf("%w: failed to store integrity finding: %w", apperrors.ErrDatabase, err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM integrity_findings WHERE last_seen_at < $1`, seenAt); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to delete resolved integrity findings", slog.Any("error", err))
+		return fmt.Errorf("%w: failed to delete resolved integrity findings: %w", apperrors.ErrDatabase, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%w: failed to commit integrity findings: %w", apperrors.ErrDatabase, err)
+	}
+	return nil
+}
+
+func (r *IntegrityRepository) ListFindings(ctx context.Context, check integrity.Check) ([]integrity.Finding, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT id, check_name, entity_type, entity_id, detail, first_seen_at, last_seen_at
+        FROM integrity_findings
+        WHERE $1 = '' OR check_name = $1
+        ORDER BY first_seen_at, id`, check)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query integrity findings", slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to list integrity findings: %w", apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	findings := []integrity.Finding{}
+	for rows.Next() {
+		var f integrity.Finding
+		if err := rows.Scan(&f.ID, &f.Check, &f.EntityType, &f.EntityID, &f.Detail, &f.FirstSeenAt, &f.LastSeenAt); err != nil {
+			return nil, fmt.Errorf("%w: failed to scan integrity finding: %w", apperrors.ErrDatabase, err)
+		}
+		findings = append(findings, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: failed to iterate integrity findings: %w", apperrors.ErrDatabase, err)
+	}
+	return findings, nil
+}
@@ -0,0 +1,72 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"billing-engine/internal/domain/integrity"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntegrityRepository(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewIntegrityRepository(db, testLogger)
+	ctx := context.Background()
+	customerID, created := createTestLoan(t, db, day("2025-01-06"), "")
+
+	for _, check := range integrity.Checks {
+		findings, err := repo.Detect(ctx, check)
+		require.NoError(t, err)
+		assert.Empty(t, findings, "a freshly booked loan passes %s", check)
+	}
+
+	// Mark a week paid behind the ledger's back and the loan paid off.
+	_, err := db.ExecContext(ctx, `UPDATE loan_schedule SET paid_amount = 110, status = 'PAID' WHERE loan_id = $1 AND week_number = 1`, created.ID)
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, `UPDATE loans SET status = 'PAID_OFF' WHERE id = $1`, created.ID)
+	require.NoError(t, err)
+	// The remaining checks need rows the foreign keys would refuse.
+	_, err = db.ExecContext(ctx, `PRAGMA foreign_keys = OFF`)
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, `UPDATE customers SET loan_id = 999 WHERE id = $1`, customerID)
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, `
+        INSERT INTO loan_schedule (loan_id, week_number, due_date, due_amount, status, created_at, updated_at)
+        VALUES (998, 1, '2025-01-13', 110, 'PENDING', $1, $1)`, time.Now().UTC())
+	require.NoError(t, err)
+
+	detect := func(check integrity.Check) []integrity.Finding {
+		findings, err := repo.Detect(ctx, check)
+		require.NoError(t, err)
+		return findings
+	}
+	assert.Equal(t, []integrity.Finding{integrity.CustomerLoanMissing(customerID, 999)}, detect(integrity.CheckCustomerLoanMissing))
+	assert.Equal(t, []integrity.Finding{integrity.LedgerMismatch(created.ID, 110, 0)}, detect(integrity.CheckLedgerMismatch))
+	assert.Equal(t, []integrity.Finding{integrity.PaidOffUnpaid(created.ID, 2)}, detect(integrity.CheckPaidOffUnpaid))
+	assert.Equal(t, []integrity.Finding{integrity.OrphanSchedule(998, 1)}, detect(integrity.CheckOrphanSchedule))
+
+	first := time.Date(2025, 3, 10, 4, 15, 0, 0, time.UTC)
+	require.NoError(t, repo.ReplaceFindings(ctx, first, []integrity.Finding{
+		integrity.PaidOffUnpaid(created.ID, 2), integrity.OrphanSchedule(998, 1),
+	}))
+	second := first.AddDate(0, 0, 1)
+	require.NoError(t, repo.ReplaceFindings(ctx, second, []integrity.Finding{integrity.PaidOffUnpaid(created.ID, 1)}))
+
+	findings, err := repo.ListFindings(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, findings, 1, "the orphan schedule was not seen again")
+	f := findings[0]
+	assert.Equal(t, integrity.CheckPaidOffUnpaid, f.Check)
+	assert.Equal(t, created.ID, f.EntityID)
+	assert.Equal(t, "loan is PAID_OFF with 1 unpaid installments", f.Detail)
+	assert.Equal(t, first, f.FirstSeenAt.UTC())
+	assert.Equal(t, second, f.LastSeenAt.UTC())
+
+	findings, err = repo.ListFindings(ctx, integrity.CheckOrphanSchedule)
+	require.NoError(t, err)
+	assert.Empty(t, findings)
+}
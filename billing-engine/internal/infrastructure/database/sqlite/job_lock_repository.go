@@ -0,0 +1,51 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"billing-engine/internal/jobs"
+	"billing-engine/internal/pkg/apperrors"
+)
+
+type JobLockRepository struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+var _ jobs.Locker = (*JobLockRepository)(nil)
+
+func NewJobLockRepository(db *sql.DB, logger *slog.Logger) *JobLockRepository {
+	return &JobLockRepository{db: db, logger: logger.With("component", "JobLockRepository")}
+}
+
+func (r *JobLockRepository) TryLock(ctx context.Context, name, owner string, now, expires time.Time) (bool, error) {
+	var holder string
+	err := r.db.QueryRowContext(ctx, `
+        INSERT INTO job_locks (name, owner, expires_at) VALUES ($1, $2, $4)
+        ON CONFLICT (name) DO UPDATE SET owner = excluded.owner, expires_at = excluded.expires_at
+        WHERE job_locks.owner = excluded.owner OR job_locks.expires_at <= $3
+        RETURNING owner`,
+		name, owner, now.UTC(), expires.UTC()).Scan(&holder)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to take job lock", slog.String("name", name), slog.Any("error", err))
+		return false, fmt.Errorf("%w: failed to take job lock: %w", apperrors.ErrDatabase, err)
+	}
+	return true, nil
+}
+
+func (r *JobLockRepository) Unlock(ctx context.Context, name, owner string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM job_locks WHERE name = $1 AND owner = $2`, name, owner); err != nil {
+		return fmt.Errorf("%w: failed to release job lock: %w", apperrors.ErrDatabase, err)
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobLockRepository(t *testing.T) {
+	repo := NewJobLockRepository(openTestDB(t), testLogger)
+	ctx := context.Background()
+	now := time.Date(2025, 1, 6, 2, 0, 0, 0, time.UTC)
+	tryLock := func(owner string, at time.Time) bool {
+		t.Helper()
+		acquired, err := repo.TryLock(ctx, "DelinquencyUpdate", owner, at, at.Add(2*time.Minute))
+		require.NoError(t, err)
+		return acquired
+	}
+
+	assert.True(t, tryLock("job-1", now))
+	assert.False(t, tryLock("job-2", now.Add(time.Minute)), "held by another run")
+	assert.True(t, tryLock("job-1", now.Add(time.Minute)), "extended by its owner")
+	assert.False(t, tryLock("job-2", now.Add(2*time.Minute)), "the extension moved the expiry on")
+	assert.True(t, tryLock("job-2", now.Add(3*time.Minute)), "taken over once lapsed")
+
+	require.NoError(t, repo.Unlock(ctx, "DelinquencyUpdate", "job-1"))
+	assert.False(t, tryLock("job-3", now.Add(3*time.Minute)), "only the owner unlocks")
+	require.NoError(t, repo.Unlock(ctx, "DelinquencyUpdate", "job-2"))
+	assert.True(t, tryLock("job-3", now.Add(3*time.Minute)))
+}
@@ -0,0 +1,200 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"billing-engine/internal/jobs"
+	"billing-engine/internal/pkg/apperrors"
+
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+const jobColumns = `id, kind, status, COALESCE(dedupe_key, ''), payload, result, COALESCE(error, ''), total, processed,
+        attempts, COALESCE(worker, ''), created_at, started_at, heartbeat_at, finished_at`
+
+// listJobsMax bounds List when the filter sets no limit.
+const listJobsMax = 1000
+
+type JobRepository struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+var _ jobs.Store = (*JobRepository)(nil)
+
+func NewJobRepository(db *sql.DB, logger *slog.Logger) *JobRepository {
+	return &JobRepository{db: db, logger: logger.With("component", "JobRepository")}
+}
+
+func (r *JobRepository) Create(ctx context.Context, job *jobs.Job) error {
+	_, err := r.db.ExecContext(ctx, `
+        INSERT INTO jobs (id, kind, status, dedupe_key, payload, total, created_at)
+        VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7)`,
+		job.ID, job.Kind, string(job.Status), job.DedupeKey, nullJSON(job.Payload), job.Total, job.CreatedAt.UTC())
+	if err != nil {
+		if sqliteCode(err) == sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+			return fmt.Errorf("%w: job %s is already queued", apperrors.ErrAlreadyExists, job.DedupeKey)
+		}
+		r.logger.ErrorContext(ctx, "Failed to queue job", slog.String("kind", job.Kind), slog.Any("error", err))
+		return fmt.Errorf("%w: failed to queue job: %w", apperrors.ErrDatabase, err)
+	}
+	return nil
+}
+
+// Claim passes the kinds as a JSON array because SQLite has no array
+// parameters.
+func (r *JobRepository) Claim(ctx context.Context, kinds []string, worker string, now, staleBefore time.Time) (*jobs.Job, error) {
+	kindsJSON, err := json.Marshal(kinds)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", apperrors.ErrInternalServer, err)
+	}
+	job, err := scanJob(r.db.QueryRowContext(ctx, `
+        UPDATE jobs
+        SET status = 'RUNNING', worker = $2, attempts = attempts + 1, started_at = $3, heartbeat_at = $3
+        WHERE id = (
+            SELECT id FROM jobs
+            WHERE kind IN (SELECT value FROM json_each($1))
+              AND (status = 'QUEUED' OR (status = 'RUNNING' AND heartbeat_at < $4))
+            ORDER BY created_at
+            LIMIT 1)
+        RETURNING `+jobColumns,
+		string(kindsJSON), worker, now.UTC(), staleBefore.UTC()))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to claim job: %w", apperrors.ErrDatabase, err)
+	}
+	return job, nil
+}
+
+func (r *JobRepository) Progress(ctx context.Context, id string, processed int, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE jobs SET processed = $2, heartbeat_at = $3 WHERE id = $1 AND status = 'RUNNING'`,
+		id, processed, at.UTC())
+	if err != nil {
+		return fmt.Errorf("%w: failed to record job progress: %w", apperrors.ErrDatabase, err)
+	}
+	return nil
+}
+
+func (r *JobRepository) Heartbeat(ctx context.Context, id string, at time.Time) error {
+	if _, err := r.db.ExecContext(ctx, `UPDATE jobs SET heartbeat_at = $2 WHERE id = $1 AND status = 'RUNNING'`, id, at.UTC()); err != nil {
+		return fmt.Errorf("%w: failed to renew job lease: %w", apperrors.ErrDatabase, err)
+	}
+	return nil
+}
+
+func (r *JobRepository) Finish(ctx context.Context, job *jobs.Job) error {
+	_, err := r.db.ExecContext(ctx, `
+        UPDATE jobs
+        SET status = $2, result = $3, error = NULLIF($4, ''), processed = $5, finished_at = $6, payload = NULL
+        WHERE id = $1`,
+		job.ID, string(job.Status), nullJSON(job.Result), job.Error, job.Processed, timeArg(job.FinishedAt))
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to record finished job", slog.String("jobId", job.ID), slog.Any("error", err))
+		return fmt.Errorf("%w: failed to record finished job: %w", apperrors.ErrDatabase, err)
+	}
+	return nil
+}
+
+func (r *JobRepository) Requeue(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, `
+        UPDATE jobs SET status = 'QUEUED', worker = NULL, started_at = NULL, heartbeat_at = NULL
+        WHERE id = $1 AND status = 'RUNNING'`, id)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to requeue job", slog.String("jobId", id), slog.Any("error", err))
+		return fmt.Errorf("%w: failed to requeue job: %w", apperrors.ErrDatabase, err)
+	}
+	return nil
+}
+
+func (r *JobRepository) Get(ctx context.Context, id string) (*jobs.Job, error) {
+	job, err := scanJob(r.db.QueryRowContext(ctx, `SELECT `+jobColumns+` FROM jobs WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: job %s", apperrors.ErrNotFound, id)
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to get job", slog.String("jobId", id), slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to get job: %w", apperrors.ErrDatabase, err)
+	}
+	return job, nil
+}
+
+func (r *JobRepository) List(ctx context.Context, f jobs.Filter) ([]jobs.Job, error) {
+	limit := f.Limit
+	if limit <= 0 {
+		limit = listJobsMax
+	}
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT `+jobColumns+`
+        FROM jobs
+        WHERE ($1 = '' OR kind = $1) AND ($2 = '' OR status = $2)
+        ORDER BY created_at DESC
+        LIMIT $3`, f.Kind, string(f.Status), limit)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query jobs", slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to list jobs: %w", apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	found := []jobs.Job{}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to scan job: %w", apperrors.ErrDatabase, err)
+		}
+		found = append(found, *job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: failed to iterate jobs: %w", apperrors.ErrDatabase, err)
+	}
+	return found, nil
+}
+
+func (r *JobRepository) CountQueued(ctx context.Context) (int, error) {
+	var n int
+	if err := r.db.QueryRowContext(ctx, `SELECT count(*) FROM jobs WHERE status = 'QUEUED'`).Scan(&n); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to count queued jobs", slog.Any("error", err))
+		return 0, fmt.Errorf("%w: failed to count queued jobs: %w", apperrors.ErrDatabase, err)
+	}
+	return n, nil
+}
+
+func (r *JobRepository) DeleteFinishedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM jobs WHERE finished_at < $1`, cutoff.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("%w: failed to delete expired jobs: %w", apperrors.ErrDatabase, err)
+	}
+	return res.RowsAffected()
+}
+
+func scanJob(row rowScanner) (*jobs.Job, error) {
+	var job jobs.Job
+	var payload, result sql.NullString
+	if err := row.Scan(&job.ID, &job.Kind, &job.Status, &job.DedupeKey, &payload, &result, &job.Error, &job.Total, &job.Processed,
+		&job.Attempts, &job.Worker, &job.CreatedAt, &job.StartedAt, &job.HeartbeatAt, &job.FinishedAt); err != nil {
+		return nil, err
+	}
+	if payload.Valid {
+		job.Payload = json.RawMessage(payload.String)
+	}
+	if result.Valid {
+		job.Result = json.RawMessage(result.String)
+	}
+	return &job, nil
+}
+
+func nullJSON(doc []byte) any {
+	if len(doc) == 0 {
+		return nil
+	}
+	return string(doc)
+}
@@ -0,0 +1,97 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"billing-engine/internal/jobs"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobRepository(t *testing.T) {
+	repo := NewJobRepository(openTestDB(t), testLogger)
+	ctx := context.Background()
+	queuedAt := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+
+	for i, job := range []jobs.Job{
+		{ID: "job-1", Kind: "customer-import", Payload: json.RawMessage(`[{"Line":2}]`), Total: 1},
+		{ID: "job-2", Kind: "event-replay", Payload: json.RawMessage(`{}`)},
+		{ID: "job-3", Kind: "batch.DelinquencyUpdate", DedupeKey: "batch.DelinquencyUpdate@2025-01-06T02:00:00Z"},
+	} {
+		job.Status = jobs.StatusQueued
+		job.CreatedAt = queuedAt.Add(time.Duration(i) * time.Minute)
+		require.NoError(t, repo.Create(ctx, &job))
+	}
+	dup := jobs.Job{ID: "job-4", Kind: "batch.DelinquencyUpdate", Status: jobs.StatusQueued,
+		DedupeKey: "batch.DelinquencyUpdate@2025-01-06T02:00:00Z", CreatedAt: queuedAt}
+	assert.ErrorIs(t, repo.Create(ctx, &dup), apperrors.ErrAlreadyExists)
+
+	queued, err := repo.CountQueued(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, queued)
+
+	now := queuedAt.Add(time.Hour)
+	claimed, err := repo.Claim(ctx, []string{"customer-import", "event-replay"}, "host-1", now, now.Add(-2*time.Minute))
+	require.NoError(t, err)
+	require.NotNil(t, claimed)
+	assert.Equal(t, "job-1", claimed.ID, "the oldest job is claimed first")
+	assert.Equal(t, jobs.StatusRunning, claimed.Status)
+	assert.Equal(t, 1, claimed.Attempts)
+	assert.Equal(t, "host-1", claimed.Worker)
+	assert.JSONEq(t, `[{"Line":2}]`, string(claimed.Payload))
+
+	claimed, err = repo.Claim(ctx, []string{"customer-import"}, "host-2", now, now.Add(-2*time.Minute))
+	require.NoError(t, err)
+	assert.Nil(t, claimed, "a job with a live lease is not claimed twice")
+
+	later := now.Add(5 * time.Minute)
+	claimed, err = repo.Claim(ctx, []string{"customer-import"}, "host-2", later, later.Add(-2*time.Minute))
+	require.NoError(t, err)
+	require.NotNil(t, claimed, "a lapsed lease is taken over")
+	assert.Equal(t, 2, claimed.Attempts)
+	assert.Equal(t, "host-2", claimed.Worker)
+
+	require.NoError(t, repo.Progress(ctx, "job-1", 1, later))
+	finishedAt := later.Add(time.Second)
+	require.NoError(t, repo.Finish(ctx, &jobs.Job{ID: "job-1", Status: jobs.StatusSucceeded,
+		Result: json.RawMessage(`{"created":1}`), Processed: 1, FinishedAt: &finishedAt}))
+
+	got, err := repo.Get(ctx, "job-1")
+	require.NoError(t, err)
+	assert.Equal(t, jobs.StatusSucceeded, got.Status)
+	assert.JSONEq(t, `{"created":1}`, string(got.Result))
+	assert.Nil(t, got.Payload, "the payload is dropped once the job is done")
+	require.NotNil(t, got.FinishedAt)
+	assert.True(t, got.FinishedAt.Equal(finishedAt))
+
+	claimed, err = repo.Claim(ctx, []string{"event-replay"}, "host-1", later, later.Add(-2*time.Minute))
+	require.NoError(t, err)
+	require.NotNil(t, claimed)
+	require.NoError(t, repo.Requeue(ctx, claimed.ID))
+	got, err = repo.Get(ctx, claimed.ID)
+	require.NoError(t, err)
+	assert.Equal(t, jobs.StatusQueued, got.Status)
+	assert.Nil(t, got.StartedAt)
+
+	_, err = repo.Get(ctx, "job-9")
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+
+	listed, err := repo.List(ctx, jobs.Filter{})
+	require.NoError(t, err)
+	require.Len(t, listed, 3)
+	assert.Equal(t, "job-3", listed[0].ID, "newest first")
+	listed, err = repo.List(ctx, jobs.Filter{Kind: "customer-import", Status: jobs.StatusSucceeded})
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, "job-1", listed[0].ID)
+
+	deleted, err := repo.DeleteFinishedBefore(ctx, finishedAt.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+}
@@ -0,0 +1,61 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"billing-engine/internal/jobs"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobRunRepository(t *testing.T) {
+	repo := NewJobRunRepository(openTestDB(t), testLogger)
+	ctx := context.Background()
+	startedAt := time.Date(2025, 1, 6, 2, 0, 0, 0, time.UTC)
+
+	first := &jobs.Run{Name: "DelinquencyUpdate", JobID: "job-1", Status: jobs.StatusRunning, StartedAt: startedAt}
+	require.NoError(t, repo.StartRun(ctx, first))
+	snapshot := &jobs.Run{Name: "LoanSnapshot", Status: jobs.StatusRunning, StartedAt: startedAt}
+	require.NoError(t, repo.StartRun(ctx, snapshot))
+
+	first.Total, first.Processed, first.UpdatedAt = 40, 10, startedAt.Add(5*time.Second)
+	require.NoError(t, repo.UpdateRun(ctx, first))
+	got, err := repo.GetRun(ctx, "DelinquencyUpdate", first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 40, got.Total)
+	assert.Equal(t, 10, got.Processed)
+	assert.Empty(t, got.ErrorSamples)
+
+	retry := &jobs.Run{Name: "DelinquencyUpdate", JobID: "job-1", Status: jobs.StatusRunning, StartedAt: startedAt.Add(3 * time.Minute)}
+	require.NoError(t, repo.StartRun(ctx, retry))
+	got, err = repo.GetRun(ctx, "DelinquencyUpdate", first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, jobs.StatusFailed, got.Status, "the run of a job claimed again is closed")
+	assert.Equal(t, jobs.AbandonedRunError, got.Error)
+	require.NotNil(t, got.FinishedAt)
+	assert.True(t, got.FinishedAt.Equal(retry.StartedAt))
+	got, err = repo.GetRun(ctx, "LoanSnapshot", snapshot.ID)
+	require.NoError(t, err)
+	assert.Equal(t, jobs.StatusRunning, got.Status, "runs without a job are left alone")
+
+	finishedAt := retry.StartedAt.Add(time.Minute)
+	retry.Status, retry.Total, retry.Processed, retry.Failed = jobs.StatusFailed, 40, 40, 1
+	retry.ErrorSamples, retry.Error, retry.FinishedAt = []string{"loan 7: timeout"}, "1 of 40 loans failed", &finishedAt
+	require.NoError(t, repo.FinishRun(ctx, retry))
+
+	runs, err := repo.ListRuns(ctx, "DelinquencyUpdate", 0)
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	assert.Equal(t, retry.ID, runs[0].ID, "newest first")
+	assert.Equal(t, []string{"loan 7: timeout"}, runs[0].ErrorSamples)
+	assert.Equal(t, "1 of 40 loans failed", runs[0].Error)
+	assert.Equal(t, time.Minute, runs[0].Duration(finishedAt))
+
+	_, err = repo.GetRun(ctx, "LoanSnapshot", first.ID)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+}
@@ -0,0 +1,253 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/infrastructure/monitoring"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// archivedTables lists the tables of a loan and the column that names it,
+// parents first so that a restore satisfies the foreign keys as it goes.
+var archivedTables = []struct{ name, loanColumn string }{
+	{"loans", "id"},
+	{"loan_schedule", "loan_id"},
+	{"direct_debit_instructions", "loan_id"},
+	{"loan_status_snapshots", "loan_id"},
+	{"collection_assignments", "loan_id"},
+	{"collection_actions", "loan_id"},
+	{"loan_holds", "loan_id"},
+	{"notes", "loan_id"},
+	{"attachments", "loan_id"},
+	{"rate_history", "loan_id"},
+	{"schedule_adjustments", "loan_id"},
+	{"prepayments", "loan_id"},
+	{"fees", "loan_id"},
+	{"fee_waivers", "loan_id"},
+	{"tax_lines", "loan_id"},
+	{"payments", "loan_id"},
+	{"documents", "loan_id"},
+	{"loan_agreements", "loan_id"},
+}
+
+const archivedLoanColumns = `loan_id, public_id, customer_id, completed_at, archived_at`
+
+// archivableLoans selects paid-off loans completed before $1 that are not
+// held or in collections. The caller adds its own conditions after $2.
+const archivableLoans = `
+        FROM loans l
+        LEFT JOIN loan_schedule s ON s.loan_id = l.id
+        WHERE l.status = 'PAID_OFF'
+          AND NOT EXISTS (SELECT 1 FROM loan_holds h WHERE h.loan_id = l.id AND h.released_at IS NULL)
+          AND NOT EXISTS (SELECT 1 FROM collection_assignments a WHERE a.loan_id = l.id AND a.status = 'OPEN')`
+
+// restoreFixups apply to the archived rows what the foreign keys did to live
+// rows while the loan was archived.
+var restoreFixups = []string{
+	`UPDATE collection_assignments_archive SET rule_id = NULL
+        WHERE loan_id = $1 AND rule_id IS NOT NULL
+          AND NOT EXISTS (SELECT 1 FROM collection_rules r WHERE r.id = collection_assignments_archive.rule_id)`,
+	`UPDATE collection_assignments_archive SET customer_id = NULL
+        WHERE loan_id = $1 AND customer_id IS NOT NULL
+          AND NOT EXISTS (SELECT 1 FROM customers c WHERE c.id = collection_assignments_archive.customer_id)`,
+	`DELETE FROM direct_debit_instructions_archive
+        WHERE loan_id = $1
+          AND NOT EXISTS (SELECT 1 FROM mandates m WHERE m.id = direct_debit_instructions_archive.mandate_id)`,
+}
+
+func scanArchivedLoan(row rowScanner, a *loan.ArchivedLoan) error {
+	return row.Scan(&a.LoanID, &a.PublicID, &a.CustomerID, &a.CompletedAt, &a.ArchivedAt)
+}
+
+func (r *LoanRepository) ListArchivable(ctx context.Context, cutoff time.Time, limit int) ([]int64, error) {
+	start := time.Now()
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT l.id`+archivableLoans+`
+        GROUP BY l.id
+        HAVING COALESCE(MAX(s.payment_date), l.updated_at) < $1
+        ORDER BY COALESCE(MAX(s.payment_date), l.updated_at), l.id
+        LIMIT $2`, cutoff.UTC(), limit)
+	if err != nil {
+		monitoring.RecordDBQuery("ListArchivableLoans", "error", time.Since(start))
+		r.logger.ErrorContext(ctx, "Failed to query archivable loans", "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	monitoring.RecordDBQuery("ListArchivableLoans", "success", time.Since(start))
+	return ids, nil
+}
+
+func (r *LoanRepository) ArchiveLoan(ctx context.Context, loanID int64, cutoff, archivedAt time.Time) (*loan.ArchivedLoan, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to begin transaction", "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM loans WHERE id = $1)`, loanID).Scan(&exists)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to look up loan for archival", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("%w: loan %d", apperrors.ErrNotFound, loanID)
+	}
+
+	archived := loan.ArchivedLoan{LoanID: loanID, ArchivedAt: archivedAt.UTC()}
+	var completedAt string
+	err = tx.QueryRowContext(ctx, `
+        SELECT l.public_id, (SELECT c.id FROM customers c WHERE c.loan_id = l.id), COALESCE(MAX(s.payment_date), l.updated_at)`+archivableLoans+`
+          AND l.id = $2
+        GROUP BY l.id
+        HAVING COALESCE(MAX(s.payment_date), l.updated_at) < $1`, cutoff.UTC(), loanID,
+	).Scan(&archived.PublicID, &archived.CustomerID, &completedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: loan %d no longer qualifies for archival", apperrors.ErrConflict, loanID)
+	}
+	if err == nil {
+		archived.CompletedAt, err = parseTimestamp(completedAt)
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to check loan for archival", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO archived_loans (`+archivedLoanColumns+`) VALUES ($1, $2, $3, $4, $5)`,
+		loanID, archived.PublicID, archived.CustomerID, archived.CompletedAt.UTC(), archived.ArchivedAt)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to catalogue archived loan", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	for _, t := range archivedTables {
+		query := `INSERT INTO ` + t.name + `_archive SELECT * FROM ` + t.name + ` WHERE ` + t.loanColumn + ` = $1`
+		if _, err := tx.ExecContext(ctx, query, loanID); err != nil {
+			r.logger.ErrorContext(ctx, "Failed to copy rows to the archive", "loan_id", loanID, "table", t.name, "error", err)
+			return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM loans WHERE id = $1`, loanID); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to delete archived loan", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to commit transaction", "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	r.logger.InfoContext(ctx, "Loan archived in DB", "loan_id", loanID)
+	return &archived, nil
+}
+
+func (r *LoanRepository) RestoreLoan(ctx context.Context, loanID int64, restoredAt time.Time) (*loan.ArchivedLoan, bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to begin transaction", "error", err)
+		return nil, false, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	defer tx.Rollback()
+
+	var archived loan.ArchivedLoan
+	err = scanArchivedLoan(tx.QueryRowContext(ctx, `SELECT `+archivedLoanColumns+` FROM archived_loans WHERE loan_id = $1`, loanID), &archived)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, fmt.Errorf("%w: loan %d is not archived", apperrors.ErrNotFound, loanID)
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to read archived loan", "loan_id", loanID, "error", err)
+		return nil, false, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+
+	for _, query := range restoreFixups {
+		if _, err := tx.ExecContext(ctx, query, loanID); err != nil {
+			r.logger.ErrorContext(ctx, "Failed to reconcile archived rows", "loan_id", loanID, "error", err)
+			return nil, false, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+		}
+	}
+	for _, t := range archivedTables {
+		query := `INSERT INTO ` + t.name + ` SELECT * FROM ` + t.name + `_archive WHERE ` + t.loanColumn + ` = $1`
+		if _, err := tx.ExecContext(ctx, query, loanID); err != nil {
+			switch code := sqliteCode(err); code {
+			case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY, sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
+				return nil, false, fmt.Errorf("%w: restoring loan %d clashes with live %s: %w", apperrors.ErrConflict, loanID, t.name, err)
+			}
+			r.logger.ErrorContext(ctx, "Failed to restore archived rows", "loan_id", loanID, "table", t.name, "error", err)
+			return nil, false, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+		}
+	}
+	for _, t := range archivedTables {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM `+t.name+`_archive WHERE `+t.loanColumn+` = $1`, loanID); err != nil {
+			r.logger.ErrorContext(ctx, "Failed to delete archived rows", "loan_id", loanID, "table", t.name, "error", err)
+			return nil, false, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM archived_loans WHERE loan_id = $1`, loanID); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to delete archive catalogue entry", "loan_id", loanID, "error", err)
+		return nil, false, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+
+	relinked := false
+	if archived.CustomerID != nil {
+		res, err := tx.ExecContext(ctx, `UPDATE customers SET loan_id = $1, updated_at = $3 WHERE id = $2 AND loan_id IS NULL`,
+			loanID, *archived.CustomerID, restoredAt.UTC())
+		if err != nil {
+			r.logger.ErrorContext(ctx, "Failed to reassign restored loan", "loan_id", loanID, "customer_id", *archived.CustomerID, "error", err)
+			return nil, false, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+		}
+		affected, _ := res.RowsAffected()
+		relinked = affected == 1
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to commit transaction", "error", err)
+		return nil, false, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	r.logger.InfoContext(ctx, "Loan restored from archive in DB", "loan_id", loanID, "relinked", relinked)
+	return &archived, relinked, nil
+}
+
+func (r *LoanRepository) ListArchived(ctx context.Context, limit int) ([]loan.ArchivedLoan, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT `+archivedLoanColumns+`
+        FROM archived_loans
+        ORDER BY archived_at DESC, loan_id DESC
+        LIMIT $1`, limit)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query archived loans", "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	archived := []loan.ArchivedLoan{}
+	for rows.Next() {
+		var a loan.ArchivedLoan
+		if err := scanArchivedLoan(rows, &a); err != nil {
+			return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+		}
+		archived = append(archived, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	return archived, nil
+}
@@ -0,0 +1,136 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/domain/note"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoanRepositoryArchiveAndRestore(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewLoanRepository(db, clock.System(), testLogger)
+	customers := NewCustomerRepository(db, clock.System(), testLogger)
+	ctx := context.Background()
+
+	customerID, paidOff := createTestLoan(t, db, day("2022-01-03"), "ref-old")
+	_, active := createTestLoan(t, db, day("2022-01-03"), "ref-active")
+	paidAt := time.Date(2022, 1, 24, 10, 0, 0, 0, time.UTC)
+	_, err := db.ExecContext(ctx, `UPDATE loan_schedule SET status = 'PAID', paid_amount = due_amount, payment_date = $1 WHERE loan_id = $2`, paidAt, paidOff.ID)
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, `UPDATE loans SET status = 'PAID_OFF' WHERE id = $1`, paidOff.ID)
+	require.NoError(t, err)
+	require.NoError(t, NewNoteRepository(db, clock.System(), testLogger).CreateNote(ctx, &note.Note{Subject: note.Subject{Type: note.SubjectLoan, ID: paidOff.ID}, Author: "ops", Body: "settled"}))
+
+	cutoff := day("2023-01-01")
+	ids, err := repo.ListArchivable(ctx, cutoff, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{paidOff.ID}, ids, "only the paid-off loan qualifies")
+
+	_, err = repo.ArchiveLoan(ctx, active.ID, cutoff, day("2025-01-01"))
+	assert.ErrorIs(t, err, apperrors.ErrConflict)
+	_, err = repo.ArchiveLoan(ctx, 999, cutoff, day("2025-01-01"))
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+
+	archived, err := repo.ArchiveLoan(ctx, paidOff.ID, cutoff, day("2025-01-01"))
+	require.NoError(t, err)
+	assert.Equal(t, paidOff.PublicID, archived.PublicID)
+	assert.Equal(t, &customerID, archived.CustomerID)
+	assert.True(t, paidAt.Equal(archived.CompletedAt))
+
+	_, err = repo.GetLoanByID(ctx, paidOff.ID)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	cust, err := customers.FindByID(ctx, customerID)
+	require.NoError(t, err)
+	assert.Nil(t, cust.LoanID, "the customer is unassigned")
+	listed, err := repo.ListArchived(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, paidOff.ID, listed[0].LoanID)
+
+	restored, relinked, err := repo.RestoreLoan(ctx, paidOff.ID, day("2025-02-01"))
+	require.NoError(t, err)
+	assert.True(t, relinked)
+	assert.Equal(t, paidOff.ID, restored.LoanID)
+
+	back, err := repo.GetLoanByID(ctx, paidOff.ID)
+	require.NoError(t, err)
+	assert.Equal(t, paidOff.PublicID, back.PublicID)
+	schedule, err := repo.GetScheduleByLoanID(ctx, paidOff.ID)
+	require.NoError(t, err)
+	assert.Len(t, schedule, 3)
+	cust, err = customers.FindByID(ctx, customerID)
+	require.NoError(t, err)
+	require.NotNil(t, cust.LoanID)
+	assert.Equal(t, paidOff.ID, *cust.LoanID)
+	var leftover int
+	require.NoError(t, db.QueryRowContext(ctx, `SELECT (SELECT COUNT(*) FROM loan_schedule_archive) + (SELECT COUNT(*) FROM notes_archive) + (SELECT COUNT(*) FROM archived_loans)`).Scan(&leftover))
+	assert.Zero(t, leftover, "the archive is emptied")
+
+	_, _, err = repo.RestoreLoan(ctx, paidOff.ID, day("2025-02-01"))
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+}
+
+func TestLoanRepositoryArchiveKeepsClosedPeriodReports(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewLoanRepository(db, clock.System(), testLogger)
+	ctx := context.Background()
+
+	_, paidOff := createTestLoan(t, db, day("2022-01-03"), "ref-old")
+	paidAt := time.Date(2022, 1, 24, 10, 0, 0, 0, time.UTC)
+	_, err := db.ExecContext(ctx, `UPDATE loan_schedule SET status = 'PAID', paid_amount = due_amount, payment_date = $1 WHERE loan_id = $2`, paidAt, paidOff.ID)
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, `
+        INSERT INTO payments (loan_id, schedule_id, amount, channel, paid_at, created_at)
+        SELECT loan_id, id, due_amount, 'CASH', $1, $1 FROM loan_schedule WHERE loan_id = $2`, paidAt, paidOff.ID)
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, `UPDATE loans SET status = 'PAID_OFF', created_at = $1 WHERE id = $2`, day("2022-01-03"), paidOff.ID)
+	require.NoError(t, err)
+
+	postedAt := time.Date(2022, 1, 10, 9, 0, 0, 0, time.UTC)
+	fee := &loan.Fee{LoanID: paidOff.ID, Type: loan.FeeBounce, Amount: 25, Reason: "direct debit returned", PostedAt: postedAt,
+		Tax: &loan.TaxLine{LoanID: paidOff.ID, Jurisdiction: "ID", Rate: 0.11, TaxableAmount: 25, Amount: 2.75, CreatedAt: postedAt}}
+	require.NoError(t, repo.PostFee(ctx, fee))
+	waiver := &loan.FeeWaiver{FeeID: fee.ID, LoanID: paidOff.ID, Reason: "bank error", Status: loan.WaiverPending, RequestedAt: postedAt}
+	require.NoError(t, repo.RequestFeeWaiver(ctx, waiver))
+	waiver.Status = loan.WaiverApproved
+	waiver.DecidedAt = &postedAt
+	require.NoError(t, repo.DecideFeeWaiver(ctx, waiver))
+	_, err = repo.WriteDailySnapshots(ctx, day("2022-01-31"))
+	require.NoError(t, err)
+
+	from, to := day("2022-01-01"), day("2022-02-01")
+	report := func() ([]loan.TaxTotals, []loan.ChannelCollections, []loan.PortfolioRow) {
+		tax, err := repo.SumTaxLines(ctx, from, to)
+		require.NoError(t, err)
+		channels, err := repo.SumPaymentsByChannel(ctx, from, to)
+		require.NoError(t, err)
+		portfolio, err := repo.GetPortfolioRows(ctx, day("2022-01-31"))
+		require.NoError(t, err)
+		return tax, channels, portfolio
+	}
+	taxBefore, channelsBefore, portfolioBefore := report()
+	require.Equal(t, []loan.TaxTotals{{Jurisdiction: "ID", FeeType: loan.FeeBounce, Lines: 1, TaxableAmount: 25, Amount: 2.75, WaivedAmount: 2.75}}, taxBefore)
+	require.Len(t, channelsBefore, 1)
+	require.Len(t, portfolioBefore, 1)
+
+	_, err = repo.ArchiveLoan(ctx, paidOff.ID, day("2023-01-01"), day("2025-01-01"))
+	require.NoError(t, err)
+
+	taxAfter, channelsAfter, portfolioAfter := report()
+	assert.Equal(t, taxBefore, taxAfter, "the closed month's tax report is unchanged")
+	assert.Equal(t, channelsBefore, channelsAfter)
+	assert.Equal(t, portfolioBefore, portfolioAfter)
+	latest, ok, err := repo.LatestSnapshotDate(ctx, day("2022-02-15"))
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, day("2022-01-31").Equal(latest))
+}
@@ -0,0 +1,251 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+const (
+	feeColumns = `f.id, f.loan_id, f.fee_type, f.amount, f.reason, f.posted_by, f.posted_at, f.paid_at,
+        t.id, t.jurisdiction, t.rate, t.taxable_amount, t.amount, t.created_at`
+	feesFrom         = ` FROM fees f LEFT JOIN tax_lines t ON t.fee_id = f.id`
+	feeWaiverColumns = `id, fee_id, loan_id, reason, status, requested_by, requested_at, decided_by, decided_at`
+	// openFeesWhere keeps the loan's fees that are neither paid nor waived.
+	openFeesWhere = `
+        WHERE f.loan_id = $1 AND f.paid_at IS NULL
+          AND NOT EXISTS (SELECT 1 FROM fee_waivers w WHERE w.fee_id = f.id AND w.status = 'APPROVED')`
+)
+
+// feeRow scans a row of feeColumns, whose tax line columns are NULL when
+// the fee was not taxed.
+type feeRow struct {
+	fee          loan.Fee
+	taxID        *int64
+	jurisdiction *string
+	rate         *float64
+	taxable      *float64
+	taxAmount    *float64
+	taxedAt      *time.Time
+}
+
+func (r *feeRow) fields() []any {
+	f := &r.fee
+	return []any{&f.ID, &f.LoanID, &f.Type, &f.Amount, &f.Reason, &f.PostedBy, &f.PostedAt, &f.PaidAt,
+		&r.taxID, &r.jurisdiction, &r.rate, &r.taxable, &r.taxAmount, &r.taxedAt}
+}
+
+func (r *feeRow) result() loan.Fee {
+	f := r.fee
+	f.Waivers = []loan.FeeWaiver{}
+	if r.taxID != nil {
+		f.Tax = &loan.TaxLine{ID: *r.taxID, FeeID: f.ID, LoanID: f.LoanID, Jurisdiction: *r.jurisdiction, Rate: *r.rate,
+			TaxableAmount: *r.taxable, Amount: *r.taxAmount, CreatedAt: *r.taxedAt}
+	}
+	return f
+}
+
+func feeWaiverFields(w *loan.FeeWaiver) []any {
+	return []any{&w.ID, &w.FeeID, &w.LoanID, &w.Reason, &w.Status, &w.RequestedBy, &w.RequestedAt, &w.DecidedBy, &w.DecidedAt}
+}
+
+// PostFee inserts the fee and its tax line in a transaction, since SQLite
+// has no data-modifying WITH clause.
+func (r *LoanRepository) PostFee(ctx context.Context, fee *loan.Fee) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to begin transaction", "error", err)
+		return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `INSERT INTO fees (loan_id, fee_type, amount, reason, posted_by, posted_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+		fee.LoanID, fee.Type, fee.Amount, fee.Reason, fee.PostedBy, fee.PostedAt.UTC()).Scan(&fee.ID)
+	if sqliteCode(err) == sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY {
+		return fmt.Errorf("%w: loan %d", apperrors.ErrNotFound, fee.LoanID)
+	}
+	if err == nil && fee.Tax != nil {
+		tax := fee.Tax
+		tax.FeeID = fee.ID
+		err = tx.QueryRowContext(ctx, `
+            INSERT INTO tax_lines (fee_id, loan_id, jurisdiction, rate, taxable_amount, amount, created_at)
+            VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+			tax.FeeID, fee.LoanID, tax.Jurisdiction, tax.Rate, tax.TaxableAmount, tax.Amount, fee.PostedAt.UTC()).Scan(&tax.ID)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to insert fee", "loan_id", fee.LoanID, "error", err)
+		return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	return nil
+}
+
+// FindOldestOpenFeeForUpdate needs no row lock: the transaction already
+// holds the database's write lock.
+func (t *loanTx) FindOldestOpenFeeForUpdate(ctx context.Context, loanID int64) (*loan.Fee, error) {
+	var row feeRow
+	err := t.tx.QueryRowContext(ctx, `SELECT `+feeColumns+feesFrom+openFeesWhere+` ORDER BY f.id LIMIT 1`, loanID).Scan(row.fields()...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: loan %d has no open fee", apperrors.ErrNotFound, loanID)
+	}
+	if err != nil {
+		t.r.logger.ErrorContext(ctx, "Failed to find oldest open fee", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	fee := row.result()
+	return &fee, nil
+}
+
+func (t *loanTx) SettleFee(ctx context.Context, fee *loan.Fee) error {
+	res, err := t.tx.ExecContext(ctx, `UPDATE fees SET paid_at = $2 WHERE id = $1 AND paid_at IS NULL`, fee.ID, timeArg(fee.PaidAt))
+	if err != nil {
+		t.r.logger.ErrorContext(ctx, "Failed to settle fee", "fee_id", fee.ID, "error", err)
+		return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	if n, _ := res.RowsAffected(); n != 1 {
+		return fmt.Errorf("%w: fee %d is already paid", apperrors.ErrConflict, fee.ID)
+	}
+	return nil
+}
+
+func (r *LoanRepository) GetFees(ctx context.Context, loanID int64) ([]loan.Fee, error) {
+	fees, err := r.queryFees(ctx, `SELECT `+feeColumns+feesFrom+` WHERE f.loan_id = $1 ORDER BY f.id`, loanID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT `+feeWaiverColumns+` FROM fee_waivers WHERE loan_id = $1 ORDER BY id`, loanID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to read fee waivers", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+	byID := make(map[int64]*loan.Fee, len(fees))
+	for i := range fees {
+		byID[fees[i].ID] = &fees[i]
+	}
+	for rows.Next() {
+		var w loan.FeeWaiver
+		if err := rows.Scan(feeWaiverFields(&w)...); err != nil {
+			r.logger.ErrorContext(ctx, "Failed to scan fee waiver", "loan_id", loanID, "error", err)
+			return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+		}
+		if fee, ok := byID[w.FeeID]; ok {
+			fee.Waivers = append(fee.Waivers, w)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.ErrorContext(ctx, "Error iterating fee waivers", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	return fees, nil
+}
+
+func (r *LoanRepository) queryFees(ctx context.Context, query string, loanID int64) ([]loan.Fee, error) {
+	rows, err := r.db.QueryContext(ctx, query, loanID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to read fees", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	fees := []loan.Fee{}
+	for rows.Next() {
+		var row feeRow
+		if err := rows.Scan(row.fields()...); err != nil {
+			r.logger.ErrorContext(ctx, "Failed to scan fee", "loan_id", loanID, "error", err)
+			return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+		}
+		fees = append(fees, row.result())
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.ErrorContext(ctx, "Error iterating fees", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	return fees, nil
+}
+
+func (r *LoanRepository) RequestFeeWaiver(ctx context.Context, waiver *loan.FeeWaiver) error {
+	err := r.db.QueryRowContext(ctx, `
+        INSERT INTO fee_waivers (fee_id, loan_id, reason, status, requested_by, requested_at)
+        SELECT f.id, f.loan_id, $3, $4, $5, $6 FROM fees f WHERE f.id = $1 AND f.loan_id = $2
+        RETURNING id`,
+		waiver.FeeID, waiver.LoanID, waiver.Reason, waiver.Status, waiver.RequestedBy, waiver.RequestedAt.UTC()).Scan(&waiver.ID)
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: loan %d has no fee %d", apperrors.ErrNotFound, waiver.LoanID, waiver.FeeID)
+	}
+	if sqliteCode(err) == sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+		return fmt.Errorf("%w: fee %d already has a pending or approved waiver", apperrors.ErrConflict, waiver.FeeID)
+	}
+	r.logger.ErrorContext(ctx, "Failed to insert fee waiver", "fee_id", waiver.FeeID, "error", err)
+	return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+}
+
+func (r *LoanRepository) DecideFeeWaiver(ctx context.Context, waiver *loan.FeeWaiver) error {
+	var decidedAt any
+	if waiver.DecidedAt != nil {
+		decidedAt = waiver.DecidedAt.UTC()
+	}
+	res, err := r.db.ExecContext(ctx, `UPDATE fee_waivers SET status = $2, decided_by = $3, decided_at = $4 WHERE id = $1 AND status = 'PENDING'`,
+		waiver.ID, waiver.Status, waiver.DecidedBy, decidedAt)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to decide fee waiver", "waiver_id", waiver.ID, "error", err)
+		return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: waiver %d was already decided", apperrors.ErrConflict, waiver.ID)
+	}
+	return nil
+}
+
+func (r *LoanRepository) SumTaxLines(ctx context.Context, from, to time.Time) ([]loan.TaxTotals, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT jurisdiction, fee_type, COUNT(*), SUM(taxable_amount), SUM(amount),
+               SUM(CASE WHEN waived THEN amount ELSE 0.0 END)
+        FROM (
+            SELECT t.jurisdiction, f.fee_type, t.taxable_amount, t.amount,
+                   EXISTS (SELECT 1 FROM fee_waivers w WHERE w.fee_id = t.fee_id AND w.status = 'APPROVED') AS waived
+            FROM tax_lines t
+            JOIN fees f ON f.id = t.fee_id
+            WHERE t.created_at >= $1 AND t.created_at < $2
+            UNION ALL
+            SELECT t.jurisdiction, f.fee_type, t.taxable_amount, t.amount,
+                   EXISTS (SELECT 1 FROM fee_waivers_archive w WHERE w.fee_id = t.fee_id AND w.status = 'APPROVED')
+            FROM tax_lines_archive t
+            JOIN fees_archive f ON f.id = t.fee_id
+            WHERE t.created_at >= $1 AND t.created_at < $2
+        ) lines
+        GROUP BY jurisdiction, fee_type
+        ORDER BY jurisdiction, fee_type`, from.UTC(), to.UTC())
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to sum tax lines", "from", from, "to", to, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	totals := make([]loan.TaxTotals, 0)
+	for rows.Next() {
+		var t loan.TaxTotals
+		if err := rows.Scan(&t.Jurisdiction, &t.FeeType, &t.Lines, &t.TaxableAmount, &t.Amount, &t.WaivedAmount); err != nil {
+			return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+		}
+		totals = append(totals, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	return totals, nil
+}
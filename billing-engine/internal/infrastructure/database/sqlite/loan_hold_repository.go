@@ -0,0 +1,77 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+const holdColumns = `id, loan_id, reason, placed_by, placed_at, released_by, released_at`
+
+func holdFields(h *loan.Hold) []any {
+	return []any{&h.ID, &h.LoanID, &h.Reason, &h.PlacedBy, &h.PlacedAt, &h.ReleasedBy, &h.ReleasedAt}
+}
+
+// PlaceHold needs no lock of its own: the insert waits for the write
+// transaction of a payment in progress like any other write.
+func (r *LoanRepository) PlaceHold(ctx context.Context, hold *loan.Hold) error {
+	err := r.db.QueryRowContext(ctx, `INSERT INTO loan_holds (loan_id, reason, placed_by, placed_at) VALUES ($1, $2, $3, $4) RETURNING id`,
+		hold.LoanID, hold.Reason, hold.PlacedBy, hold.PlacedAt.UTC()).Scan(&hold.ID)
+	if err == nil {
+		return nil
+	}
+	switch sqliteCode(err) {
+	case sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
+		return fmt.Errorf("%w: loan %d", apperrors.ErrNotFound, hold.LoanID)
+	case sqlite3.SQLITE_CONSTRAINT_UNIQUE:
+		return fmt.Errorf("%w: loan %d is already on hold", apperrors.ErrConflict, hold.LoanID)
+	}
+	r.logger.ErrorContext(ctx, "Failed to insert loan hold", "loan_id", hold.LoanID, "error", err)
+	return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+}
+
+func (r *LoanRepository) ReleaseHold(ctx context.Context, loanID int64, releasedBy *string, releasedAt time.Time) (*loan.Hold, error) {
+	var h loan.Hold
+	err := r.db.QueryRowContext(ctx, `
+        UPDATE loan_holds SET released_by = $2, released_at = $3
+        WHERE loan_id = $1 AND released_at IS NULL
+        RETURNING `+holdColumns, loanID, releasedBy, releasedAt.UTC()).Scan(holdFields(&h)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: loan %d has no open hold", apperrors.ErrNotFound, loanID)
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to release loan hold", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	return &h, nil
+}
+
+func (r *LoanRepository) GetActiveHold(ctx context.Context, loanID int64) (*loan.Hold, error) {
+	return r.getActiveHold(ctx, r.db, loanID)
+}
+
+func (t *loanTx) GetActiveHold(ctx context.Context, loanID int64) (*loan.Hold, error) {
+	return t.r.getActiveHold(ctx, t.tx, loanID)
+}
+
+func (r *LoanRepository) getActiveHold(ctx context.Context, db queryRower, loanID int64) (*loan.Hold, error) {
+	var h loan.Hold
+	err := db.QueryRowContext(ctx, `SELECT `+holdColumns+` FROM loan_holds WHERE loan_id = $1 AND released_at IS NULL`, loanID).
+		Scan(holdFields(&h)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to read loan hold", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	return &h, nil
+}
@@ -0,0 +1,77 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+const rateChangeColumns = `id, loan_id, previous_rate, rate, effective_from, changed_by, created_at`
+
+// rowsQuerier is what reading the rate history needs from the database or a
+// transaction.
+type rowsQuerier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func (r *LoanRepository) GetRateHistory(ctx context.Context, loanID int64) ([]loan.RateChange, error) {
+	return r.getRateHistory(ctx, r.db, loanID)
+}
+
+func (t *loanTx) GetRateHistory(ctx context.Context, loanID int64) ([]loan.RateChange, error) {
+	return t.r.getRateHistory(ctx, t.tx, loanID)
+}
+
+func (r *LoanRepository) getRateHistory(ctx context.Context, db rowsQuerier, loanID int64) ([]loan.RateChange, error) {
+	rows, err := db.QueryContext(ctx, `SELECT `+rateChangeColumns+` FROM rate_history WHERE loan_id = $1 ORDER BY effective_from, id`, loanID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to read rate history", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	history := []loan.RateChange{}
+	for rows.Next() {
+		var c loan.RateChange
+		if err := rows.Scan(&c.ID, &c.LoanID, &c.PreviousRate, &c.Rate, &c.EffectiveFrom, &c.ChangedBy, &c.CreatedAt); err != nil {
+			r.logger.ErrorContext(ctx, "Failed to scan rate change", "loan_id", loanID, "error", err)
+			return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+		}
+		history = append(history, c)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.ErrorContext(ctx, "Error iterating rate history", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	return history, nil
+}
+
+func (t *loanTx) RepriceLoan(ctx context.Context, repricing *loan.Repricing) error {
+	change := &repricing.Change
+	res, err := t.tx.ExecContext(ctx, `UPDATE loans SET interest_rate = $1, weekly_payment_amount = $2, total_loan_amount = $3, updated_at = $4 WHERE id = $5`,
+		change.Rate, repricing.WeeklyPaymentAmount, repricing.TotalLoanAmount, now(t.r.clock), change.LoanID)
+	if err != nil {
+		t.r.logger.ErrorContext(ctx, "Failed to update loan terms", "loan_id", change.LoanID, "error", err)
+		return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	if n, _ := res.RowsAffected(); n != 1 {
+		t.r.logger.ErrorContext(ctx, "Loan repricing affected zero rows", "loan_id", change.LoanID)
+		return fmt.Errorf("%w: loan repricing affected zero rows", apperrors.ErrDatabase)
+	}
+	err = t.tx.QueryRowContext(ctx, `
+        INSERT INTO rate_history (loan_id, previous_rate, rate, effective_from, changed_by, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING id`,
+		change.LoanID, change.PreviousRate, change.Rate, dateArg(change.EffectiveFrom), change.ChangedBy, change.CreatedAt.UTC(),
+	).Scan(&change.ID)
+	if err != nil {
+		t.r.logger.ErrorContext(ctx, "Failed to insert rate change", "loan_id", change.LoanID, "error", err)
+		return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	t.r.logger.InfoContext(ctx, "Loan repriced in DB", "loan_id", change.LoanID, "rate", change.Rate)
+	return nil
+}
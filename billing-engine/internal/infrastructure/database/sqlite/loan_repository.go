@@ -0,0 +1,632 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/infrastructure/database/sqlbuilder"
+	"billing-engine/internal/infrastructure/monitoring"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"billing-engine/internal/pkg/scope"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const loanColumns = `id, public_id, principal_amount, interest_rate, term_weeks, weekly_payment_amount, total_loan_amount, start_date, status, days_past_due, external_ref, created_at, updated_at,
+        next_due_date, next_due_amount, installments_paid, installments_remaining, schedule_type, interest_only_weeks, balloon_amount`
+
+const scheduleColumns = `id, loan_id, week_number, due_date, due_amount, paid_amount, payment_date, status, created_at, updated_at`
+
+type LoanRepository struct {
+	db     *sql.DB
+	clock  clock.Clock
+	logger *slog.Logger
+}
+
+var _ loan.Repository = (*LoanRepository)(nil)
+
+func NewLoanRepository(db *sql.DB, clk clock.Clock, logger *slog.Logger) *LoanRepository {
+	return &LoanRepository{db: db, clock: clock.OrSystem(clk), logger: logger.With("component", "LoanRepository")}
+}
+
+func scanLoan(row rowScanner, l *loan.Loan) error {
+	return row.Scan(
+		&l.ID, &l.PublicID, &l.PrincipalAmount, &l.InterestRate, &l.TermWeeks,
+		&l.WeeklyPaymentAmount, &l.TotalLoanAmount, &l.StartDate,
+		&l.Status, &l.DaysPastDue, &l.ExternalRef, &l.CreatedAt, &l.UpdatedAt,
+		&l.NextDueDate, &l.NextDueAmount, &l.InstallmentsPaid, &l.InstallmentsRemaining,
+		&l.Structure.Type, &l.Structure.InterestOnlyWeeks, &l.Structure.BalloonAmount,
+	)
+}
+
+func scanScheduleEntry(row rowScanner, entry *loan.ScheduleEntry) error {
+	return row.Scan(
+		&entry.ID, &entry.LoanID, &entry.WeekNumber, &entry.DueDate,
+		&entry.DueAmount, &entry.PaidAmount, &entry.PaymentDate,
+		&entry.Status, &entry.CreatedAt, &entry.UpdatedAt,
+	)
+}
+
+// WithinTransaction runs fn against a loanTx and commits when fn returns nil.
+// SQLite allows one writer, so a concurrent payment waits here for the
+// transaction in progress to end.
+func (r *LoanRepository) WithinTransaction(ctx context.Context, fn func(tx loan.TxRepository) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to begin transaction", "error", err)
+		return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(&loanTx{r: r, tx: tx}); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to commit transaction", "error", err)
+		return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	return nil
+}
+
+// loanTx is the loan.TxRepository handed to WithinTransaction callbacks.
+type loanTx struct {
+	r  *LoanRepository
+	tx *sql.Tx
+}
+
+var _ loan.TxRepository = (*loanTx)(nil)
+
+// CreateLoan inserts the loan and its schedule and links the loan to the
+// customer in one transaction, so nothing is kept when the customer cannot be
+// linked. A customer whose loan is paid off can be linked to a new one. The
+// loan belongs to ctx's tenant, and only a customer of that tenant can take
+// it.
+func (r *LoanRepository) CreateLoan(ctx context.Context, customerID int64, newLoan *loan.Loan, schedule []loan.ScheduleEntry) (*loan.Loan, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to begin transaction", "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	defer tx.Rollback()
+
+	if newLoan.PublicID == uuid.Nil {
+		newLoan.PublicID = uuid.New()
+	}
+	createdAt := now(r.clock)
+	tenantID := scope.TenantOrDefault(ctx)
+
+	var loanID int64
+	err = tx.QueryRowContext(ctx, `
+        INSERT INTO loans (public_id, principal_amount, interest_rate, term_weeks, weekly_payment_amount, total_loan_amount, start_date, status, external_ref, created_at, updated_at,
+                           schedule_type, interest_only_weeks, balloon_amount, tenant_id)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $10, $11, $12, $13, $14)
+        RETURNING id`,
+		newLoan.PublicID, newLoan.PrincipalAmount, newLoan.InterestRate, newLoan.TermWeeks, newLoan.WeeklyPaymentAmount,
+		newLoan.TotalLoanAmount, dateArg(newLoan.StartDate), newLoan.Status, newLoan.ExternalRef, createdAt,
+		newLoan.Structure.Kind(), newLoan.Structure.InterestOnlyWeeks, newLoan.Structure.BalloonAmount, tenantID,
+	).Scan(&loanID)
+	if err != nil {
+		if translated := translateDBError(err, r.logger); errors.Is(translated, apperrors.ErrAlreadyExists) {
+			return nil, translated
+		}
+		r.logger.ErrorContext(ctx, "Failed to insert loan", "error", err)
+		return nil, fmt.Errorf("%w: failed to insert loan: %w", apperrors.ErrDatabase, err)
+	}
+
+	if len(schedule) > 0 {
+		stmt, err := tx.PrepareContext(ctx, `
+            INSERT INTO loan_schedule (loan_id, week_number, due_date, due_amount, status, created_at, updated_at)
+            VALUES ($1, $2, $3, $4, $5, $6, $6)`)
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to prepare schedule insert: %w", apperrors.ErrDatabase, err)
+		}
+		defer stmt.Close()
+
+		for i, entry := range schedule {
+			if _, err := stmt.ExecContext(ctx, loanID, entry.WeekNumber, dateArg(entry.DueDate), entry.DueAmount, entry.Status, createdAt); err != nil {
+				r.logger.ErrorContext(ctx, "Failed inserting schedule entry", "error", err, "entry_index", i, "loan_id", loanID)
+				return nil, fmt.Errorf("%w: failed inserting schedule entry %d: %w", apperrors.ErrDatabase, i+1, err)
+			}
+		}
+	}
+
+	res, err := tx.ExecContext(ctx, `
+        UPDATE customers
+        SET loan_id = $1, updated_at = $2
+        WHERE id = $3 AND active AND tenant_id = $4
+          AND (loan_id IS NULL OR loan_id IN (SELECT id FROM loans WHERE status = 'PAID_OFF'))`, loanID, createdAt, customerID, tenantID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to update customer with loan ID", slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to link loan to customer: %w", apperrors.ErrDatabase, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		r.logger.WarnContext(ctx, "Customer cannot take the loan: not found in the tenant, inactive or holding an active loan", slog.Int64("customerID", customerID))
+		return nil, fmt.Errorf("%w: customer %d is not found, inactive or already has an active loan", apperrors.ErrConflict, customerID)
+	}
+
+	var created loan.Loan
+	if err := scanLoan(tx.QueryRowContext(ctx, `SELECT `+loanColumns+` FROM loans WHERE id = $1`, loanID), &created); err != nil {
+		return nil, fmt.Errorf("%w: failed to read created loan: %w", apperrors.ErrDatabase, err)
+	}
+	if err := tx.Commit(); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to commit transaction", "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+
+	r.logger.InfoContext(ctx, "Loan created in DB", "loan_id", loanID, "num_entries", len(schedule))
+	return &created, nil
+}
+
+// getLoan reads one loan of ctx's tenant where column equals arg. column is
+// one of a fixed set of names, never input.
+func (r *LoanRepository) getLoan(ctx context.Context, operation, column string, arg any) (*loan.Loan, error) {
+	start := time.Now()
+	query, args := sqlbuilder.ScopeToTenant(ctx, `SELECT `+loanColumns+` FROM loans WHERE `+column+` = $1`, "tenant_id", arg)
+	var l loan.Loan
+	err := scanLoan(r.db.QueryRowContext(ctx, query, args...), &l)
+	status := "success"
+	if err != nil {
+		status = "error"
+	}
+	monitoring.RecordDBQuery(operation, status, time.Since(start))
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			r.logger.WarnContext(ctx, "Loan not found", "by", column)
+			return nil, apperrors.ErrNotFound
+		}
+		r.logger.ErrorContext(ctx, "Failed to get loan", "by", column, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	return &l, nil
+}
+
+func (r *LoanRepository) GetLoanByID(ctx context.Context, loanID int64) (*loan.Loan, error) {
+	return r.getLoan(ctx, "GetLoanByID", "id", loanID)
+}
+
+// GetLoanWithSchedule runs the two queries one after the other: the database
+// is in-process, so there is no round trip to save.
+func (r *LoanRepository) GetLoanWithSchedule(ctx context.Context, loanID int64) (*loan.Loan, error) {
+	l, err := r.GetLoanByID(ctx, loanID)
+	if err != nil {
+		return nil, err
+	}
+	if l.Schedule, err = r.GetScheduleByLoanID(ctx, loanID); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (r *LoanRepository) GetLoanByPublicID(ctx context.Context, publicID uuid.UUID) (*loan.Loan, error) {
+	return r.getLoan(ctx, "GetLoanByPublicID", "public_id", publicID)
+}
+
+func (r *LoanRepository) GetLoanByExternalRef(ctx context.Context, externalRef string) (*loan.Loan, error) {
+	return r.getLoan(ctx, "GetLoanByExternalRef", "external_ref", externalRef)
+}
+
+func (r *LoanRepository) querySchedule(ctx context.Context, loanID int64, query string, args ...any) ([]loan.ScheduleEntry, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query loan schedule", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	schedule := make([]loan.ScheduleEntry, 0)
+	for rows.Next() {
+		var entry loan.ScheduleEntry
+		if err := scanScheduleEntry(rows, &entry); err != nil {
+			r.logger.ErrorContext(ctx, "Failed to scan schedule row", "loan_id", loanID, "error", err)
+			return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+		}
+		schedule = append(schedule, entry)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.ErrorContext(ctx, "Error iterating schedule rows", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	return schedule, nil
+}
+
+func (r *LoanRepository) GetScheduleByLoanID(ctx context.Context, loanID int64) ([]loan.ScheduleEntry, error) {
+	return r.querySchedule(ctx, loanID, `
+        SELECT `+scheduleColumns+`
+        FROM loan_schedule
+        WHERE loan_id = $1
+        ORDER BY week_number ASC`, loanID)
+}
+
+func (r *LoanRepository) GetUnpaidSchedules(ctx context.Context, loanID int64) ([]loan.ScheduleEntry, error) {
+	return r.querySchedule(ctx, loanID, `
+        SELECT `+scheduleColumns+`
+        FROM loan_schedule
+        WHERE loan_id = $1 AND status != 'PAID'
+        ORDER BY due_date ASC`, loanID)
+}
+
+func (r *LoanRepository) GetLastTwoDueUnpaidSchedules(ctx context.Context, loanID int64) ([]loan.ScheduleEntry, error) {
+	return r.querySchedule(ctx, loanID, `
+        SELECT `+scheduleColumns+`
+        FROM loan_schedule
+        WHERE loan_id = $1 AND status != 'PAID' AND due_date <= $2
+        ORDER BY due_date DESC
+        LIMIT 2`, loanID, dateArg(now(r.clock)))
+}
+
+// LockLoanForPayment has nothing to do: the write transaction already holds
+// the database lock, so a concurrent payment waits in WithinTransaction
+// instead.
+func (t *loanTx) LockLoanForPayment(ctx context.Context, loanID int64) error {
+	return nil
+}
+
+func (t *loanTx) FindOldestUnpaidEntryForUpdate(ctx context.Context, loanID int64) (*loan.ScheduleEntry, error) {
+	query := `
+        SELECT ` + scheduleColumns + `
+        FROM loan_schedule
+        WHERE loan_id = $1 AND status = 'PENDING'
+        ORDER BY due_date ASC
+        LIMIT 1`
+
+	var entry loan.ScheduleEntry
+	if err := scanScheduleEntry(t.tx.QueryRowContext(ctx, query, loanID), &entry); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			t.r.logger.InfoContext(ctx, "No pending schedule entry found for update", "loan_id", loanID)
+			return nil, apperrors.ErrNotFound
+		}
+		t.r.logger.ErrorContext(ctx, "Failed to find oldest unpaid schedule entry", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	return &entry, nil
+}
+
+func (t *loanTx) UpdateScheduleEntry(ctx context.Context, entry *loan.ScheduleEntry) error {
+	res, err := t.tx.ExecContext(ctx, `
+        UPDATE loan_schedule
+        SET paid_amount = $1, payment_date = $2, status = $3, updated_at = $4
+        WHERE id = $5 AND loan_id = $6`,
+		entry.PaidAmount, timeArg(entry.PaymentDate), entry.Status, now(t.r.clock), entry.ID, entry.LoanID)
+	if err != nil {
+		t.r.logger.ErrorContext(ctx, "Failed to update schedule entry", "entry_id", entry.ID, "loan_id", entry.LoanID, "error", err)
+		return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	if n, _ := res.RowsAffected(); n != 1 {
+		t.r.logger.ErrorContext(ctx, "Schedule entry update affected zero rows", "entry_id", entry.ID, "loan_id", entry.LoanID)
+		return fmt.Errorf("%w: schedule entry update affected zero rows", apperrors.ErrDatabase)
+	}
+	return nil
+}
+
+func (t *loanTx) RecordPayment(ctx context.Context, payment *loan.Payment) error {
+	createdAt := now(t.r.clock)
+	res, err := t.tx.ExecContext(ctx, `
+        INSERT INTO payments (loan_id, schedule_id, fee_id, amount, channel, reference, collector_id, paid_at, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		payment.LoanID, payment.ScheduleID, payment.FeeID, payment.Amount, payment.Channel,
+		payment.Reference, payment.CollectorID, payment.PaidAt.UTC(), createdAt)
+	if err != nil {
+		return translateDBError(err, t.r.logger.With("loan_id", payment.LoanID, "schedule_id", payment.ScheduleID, "fee_id", payment.FeeID))
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	payment.ID = id
+	payment.CreatedAt = createdAt
+	return nil
+}
+
+func (t *loanTx) UpdateLoanStatus(ctx context.Context, loanID int64, status loan.LoanStatus) error {
+	// Paid-off loans drop out of the nightly job, so their days past due
+	// are cleared here.
+	res, err := t.tx.ExecContext(ctx, `UPDATE loans SET status = $1, days_past_due = CASE WHEN $1 = 'PAID_OFF' THEN 0 ELSE days_past_due END, updated_at = $2 WHERE id = $3`,
+		status, now(t.r.clock), loanID)
+	if err != nil {
+		t.r.logger.ErrorContext(ctx, "Failed to update loan status", "loan_id", loanID, "status", status, "error", err)
+		return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	if n, _ := res.RowsAffected(); n != 1 {
+		t.r.logger.ErrorContext(ctx, "Loan status update affected zero rows", "loan_id", loanID, "status", status)
+		return fmt.Errorf("%w: loan status update affected zero rows", apperrors.ErrDatabase)
+	}
+	t.r.logger.InfoContext(ctx, "Loan status updated in DB", "loan_id", loanID, "new_status", status)
+	return nil
+}
+
+func (t *loanTx) CheckIfAllPaymentsMade(ctx context.Context, loanID int64) (bool, error) {
+	var count int
+	err := t.tx.QueryRowContext(ctx, `
+        SELECT (SELECT COUNT(*) FROM loan_schedule WHERE loan_id = $1 AND status != 'PAID')
+             + (SELECT COUNT(*) FROM fees f`+openFeesWhere+`)`, loanID).Scan(&count)
+	if err != nil {
+		t.r.logger.ErrorContext(ctx, "Failed to count non-paid schedule entries and fees", "loan_id", loanID, "error", err)
+		return false, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	return count == 0, nil
+}
+
+func (r *LoanRepository) ListBalanceItems(ctx context.Context, loanID int64) ([]loan.BalanceItem, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT amount, end_to_end_id
+        FROM direct_debit_instructions
+        WHERE loan_id = $1 AND status = 'UNAPPLIED'
+        ORDER BY id`, loanID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to list balance items", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	items := make([]loan.BalanceItem, 0)
+	for rows.Next() {
+		var endToEndID string
+		item := loan.BalanceItem{Kind: loan.BalanceSuspense}
+		if err := rows.Scan(&item.Amount, &endToEndID); err != nil {
+			return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+		}
+		item.Description = "direct debit " + endToEndID + " collected but not posted"
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	rows.Close()
+
+	fees, err := r.queryFees(ctx, `SELECT `+feeColumns+feesFrom+openFeesWhere+` ORDER BY f.id`, loanID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range fees {
+		items = append(items, fees[i].BalanceItem())
+		if fees[i].Tax != nil {
+			items = append(items, fees[i].TaxBalanceItem())
+		}
+	}
+	return items, nil
+}
+
+// GetLastModified covers the schedule rows and holds as well as the loan,
+// like the PostgreSQL implementation. The scalar MAX returns NULL when any
+// argument is NULL, so each subquery falls back to the loan's updated_at.
+func (r *LoanRepository) GetLastModified(ctx context.Context, loanID int64) (time.Time, error) {
+	query, args := sqlbuilder.ScopeToTenant(ctx, `
+        SELECT MAX(l.updated_at,
+               COALESCE((SELECT MAX(s.updated_at) FROM loan_schedule s WHERE s.loan_id = l.id), l.updated_at),
+               COALESCE((SELECT MAX(COALESCE(h.released_at, h.placed_at)) FROM loan_holds h WHERE h.loan_id = l.id), l.updated_at))
+        FROM loans l
+        WHERE l.id = $1`, "l.tenant_id", loanID)
+	start := time.Now()
+
+	var lastModified string
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(&lastModified)
+	status := "success"
+	if err != nil {
+		status = "error"
+	}
+	monitoring.RecordDBQuery("GetLastModified", status, time.Since(start))
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			r.logger.WarnContext(ctx, "Loan not found", "loan_id", loanID)
+			return time.Time{}, apperrors.ErrNotFound
+		}
+		r.logger.ErrorContext(ctx, "Failed to get loan last modified time", "loan_id", loanID, "error", err)
+		return time.Time{}, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	t, err := parseTimestamp(lastModified)
+	if err != nil {
+		return time.Time{}, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	return t, nil
+}
+
+func (r *LoanRepository) SumPaymentsByChannel(ctx context.Context, from, to time.Time) ([]loan.ChannelCollections, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT channel, COUNT(*), COALESCE(SUM(amount), 0.0)
+        FROM (
+            SELECT channel, amount FROM payments WHERE paid_at >= $1 AND paid_at < $2
+            UNION ALL
+            SELECT channel, amount FROM payments_archive WHERE paid_at >= $1 AND paid_at < $2
+            UNION ALL
+            SELECT channel, amount FROM prepayments WHERE paid_at >= $1 AND paid_at < $2
+            UNION ALL
+            SELECT channel, amount FROM prepayments_archive WHERE paid_at >= $1 AND paid_at < $2
+        ) received
+        GROUP BY channel
+        ORDER BY channel`, from.UTC(), to.UTC())
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to sum payments by channel", "from", from, "to", to, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	totals := make([]loan.ChannelCollections, 0)
+	for rows.Next() {
+		var c loan.ChannelCollections
+		if err := rows.Scan(&c.Channel, &c.Payments, &c.Amount); err != nil {
+			return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+		}
+		totals = append(totals, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	return totals, nil
+}
+
+func (r *LoanRepository) ListPayments(ctx context.Context, loanID int64, limit int) ([]loan.Payment, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT id, loan_id, schedule_id, fee_id, amount, channel, reference, collector_id, paid_at, created_at
+        FROM payments WHERE loan_id = $1
+        ORDER BY paid_at DESC, id DESC
+        LIMIT $2`, loanID, limit)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to list payments", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	payments := make([]loan.Payment, 0)
+	for rows.Next() {
+		var p loan.Payment
+		if err := rows.Scan(&p.ID, &p.LoanID, &p.ScheduleID, &p.FeeID, &p.Amount, &p.Channel, &p.Reference, &p.CollectorID, &p.PaidAt, &p.CreatedAt); err != nil {
+			return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+		}
+		payments = append(payments, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	return payments, nil
+}
+
+// searchPaymentsQuery reads payments and prepayments as one ledger; SQLite
+// pushes the conditions on r down into both arms.
+const searchPaymentsQuery = `
+        SELECT l.id, l.public_id, l.status, l.days_past_due, c.id, c.name,
+               r.id, r.amount, r.channel, r.reference, r.paid_at, r.prepayment
+        FROM (
+            SELECT id, loan_id, amount, channel, reference, paid_at, FALSE AS prepayment FROM payments
+            UNION ALL
+            SELECT id, loan_id, amount, channel, reference, paid_at, TRUE AS prepayment FROM prepayments
+        ) r
+        JOIN loans l ON l.id = r.loan_id
+        LEFT JOIN customers c ON c.loan_id = l.id`
+
+const searchCustomersQuery = `
+        SELECT l.id, l.public_id, l.status, l.days_past_due, c.id, c.name
+        FROM customers c
+        JOIN loans l ON l.id = c.loan_id`
+
+func (r *LoanRepository) SearchLoans(ctx context.Context, q loan.LoanSearch) ([]loan.SearchMatch, error) {
+	var b *sqlbuilder.SelectBuilder
+	if q.SearchesPayments() {
+		low, high := q.AmountRange()
+		b = sqlbuilder.Select(searchPaymentsQuery).
+			WhereIf(q.Reference != "", "r.reference = ?", q.Reference).
+			WhereIf(q.Amount != nil, "r.amount BETWEEN ? AND ?", low, high).
+			WhereIf(!q.From.IsZero(), "r.paid_at >= ? AND r.paid_at < ?", q.From.UTC(), q.To.UTC()).
+			OrderBy("", nil, "r.paid_at DESC, r.id DESC")
+	} else {
+		b = sqlbuilder.Select(searchCustomersQuery).OrderBy("", nil, "c.name, l.id")
+	}
+	query, args, err := b.
+		WhereIf(q.CustomerName != "", `lower(c.name) LIKE lower(?) ESCAPE '\'`, sqlbuilder.Contains(q.CustomerName)).
+		WhereTenant(ctx, "l.tenant_id").
+		Page(q.Limit, 0).
+		Build()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to search loans", "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	matches := make([]loan.SearchMatch, 0)
+	for rows.Next() {
+		var m loan.SearchMatch
+		dest := []any{&m.LoanID, &m.LoanPublicID, &m.Status, &m.DaysPastDue, &m.CustomerID, &m.CustomerName}
+		if q.SearchesPayments() {
+			m.Payment = &loan.PaymentMatch{}
+			dest = append(dest, &m.Payment.ID, &m.Payment.Amount, &m.Payment.Channel, &m.Payment.Reference, &m.Payment.PaidAt, &m.Payment.Prepayment)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+		}
+		matches = append(matches, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	return matches, nil
+}
+
+func (r *LoanRepository) GetActiveLoanTenants(ctx context.Context) (map[int64]string, error) {
+	query, args := sqlbuilder.ScopeToTenant(ctx, `SELECT id, tenant_id FROM loans WHERE status = $1`, "tenant_id", loan.StatusActive)
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to query active loans", slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to query active loans: %w", apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	tenants := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var tenantID string
+		if err := rows.Scan(&id, &tenantID); err != nil {
+			return nil, fmt.Errorf("%w: failed scanning active loan: %w", apperrors.ErrDatabase, err)
+		}
+		tenants[id] = tenantID
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: error iterating active loans: %w", apperrors.ErrDatabase, err)
+	}
+	return tenants, nil
+}
+
+func (r *LoanRepository) UpdateDaysPastDue(ctx context.Context, loanID int64, daysPastDue int) error {
+	start := time.Now()
+	_, err := r.db.ExecContext(ctx, `UPDATE loans SET days_past_due = $1, updated_at = $2 WHERE id = $3 AND days_past_due <> $1`,
+		daysPastDue, now(r.clock), loanID)
+	if err != nil {
+		monitoring.RecordDBQuery("UpdateDaysPastDue", "error", time.Since(start))
+		r.logger.ErrorContext(ctx, "Failed to update days past due", "loan_id", loanID, "error", err)
+		return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	monitoring.RecordDBQuery("UpdateDaysPastDue", "success", time.Since(start))
+	return nil
+}
+
+func (r *LoanRepository) StreamLoans(ctx context.Context, filter loan.LoanFilter, fn func(*loan.Loan) error) error {
+	query, args, err := sqlbuilder.Select(`SELECT `+loanColumns+` FROM loans`).
+		Where("id > ?", filter.AfterID).
+		WhereTenant(ctx, "tenant_id").
+		WhereIf(filter.MinDaysPastDue > 0, "days_past_due >= ?", filter.MinDaysPastDue).
+		WhereIf(filter.Status != "", "status = ?", filter.Status).
+		OrderBy("", nil, "id").
+		Build()
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		monitoring.RecordDBQuery("StreamLoans", "error", time.Since(start))
+		r.logger.ErrorContext(ctx, "Failed to query loans for streaming", "after_id", filter.AfterID, "error", err)
+		return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var l loan.Loan
+		if err := scanLoan(rows, &l); err != nil {
+			monitoring.RecordDBQuery("StreamLoans", "error", time.Since(start))
+			return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+		}
+		if err := fn(&l); err != nil {
+			monitoring.RecordDBQuery("StreamLoans", "aborted", time.Since(start))
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		monitoring.RecordDBQuery("StreamLoans", "error", time.Since(start))
+		return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+
+	monitoring.RecordDBQuery("StreamLoans", "success", time.Since(start))
+	return nil
+}
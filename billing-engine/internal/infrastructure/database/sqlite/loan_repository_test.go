@@ -0,0 +1,1003 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"billing-engine/internal/pkg/scope"
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := Open(context.Background(), memoryPath, testLogger)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func day(s string) time.Time {
+	d, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// createTestLoan stores a customer and a three week loan starting on start
+// and returns both IDs.
+func createTestLoan(t *testing.T, db *sql.DB, start time.Time, externalRef string) (int64, *loan.Loan) {
+	t.Helper()
+	ctx := context.Background()
+	cust := customer.NewCustomer("Jane Doe", "1 Main St")
+	require.NoError(t, NewCustomerRepository(db, clock.System(), testLogger).Save(ctx, cust))
+
+	newLoan := &loan.Loan{
+		PrincipalAmount:     300,
+		InterestRate:        0.1,
+		TermWeeks:           3,
+		WeeklyPaymentAmount: 110,
+		TotalLoanAmount:     330,
+		StartDate:           start,
+		Status:              loan.StatusActive,
+	}
+	if externalRef != "" {
+		newLoan.ExternalRef = &externalRef
+	}
+	schedule := make([]loan.ScheduleEntry, 3)
+	for i := range schedule {
+		schedule[i] = loan.ScheduleEntry{
+			WeekNumber: i + 1,
+			DueDate:    start.AddDate(0, 0, 7*(i+1)),
+			DueAmount:  110,
+			Status:     loan.PaymentStatusPending,
+		}
+	}
+	created, err := NewLoanRepository(db, clock.System(), testLogger).CreateLoan(ctx, cust.CustomerID, newLoan, schedule)
+	require.NoError(t, err)
+	return cust.CustomerID, created
+}
+
+func TestLoanRepositoryCreateAndRead(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewLoanRepository(db, clock.System(), testLogger)
+	ctx := context.Background()
+
+	customerID, created := createTestLoan(t, db, day("2025-01-06"), "ref-1")
+	assert.NotZero(t, created.ID)
+	assert.Equal(t, day("2025-01-06"), created.StartDate)
+	assert.Equal(t, loan.StatusActive, created.Status)
+	assert.Equal(t, loan.ScheduleStandard, created.Structure.Type)
+
+	byPublicID, err := repo.GetLoanByPublicID(ctx, created.PublicID)
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, byPublicID.ID)
+
+	byRef, err := repo.GetLoanByExternalRef(ctx, "ref-1")
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, byRef.ID)
+
+	_, err = repo.GetLoanByID(ctx, created.ID+1)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+
+	linked, err := NewCustomerRepository(db, clock.System(), testLogger).FindByLoanID(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, customerID, linked.CustomerID)
+
+	schedule, err := repo.GetScheduleByLoanID(ctx, created.ID)
+	require.NoError(t, err)
+	require.Len(t, schedule, 3)
+	assert.Equal(t, day("2025-01-13"), schedule[0].DueDate)
+	assert.Nil(t, schedule[0].PaymentDate)
+
+	withSchedule, err := repo.GetLoanWithSchedule(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, withSchedule.ID)
+	assert.Equal(t, schedule, withSchedule.Schedule)
+	_, err = repo.GetLoanWithSchedule(ctx, created.ID+1)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+
+	items, err := repo.ListBalanceItems(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Empty(t, items)
+
+	overdue, err := repo.GetLastTwoDueUnpaidSchedules(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Len(t, overdue, 2)
+}
+
+func TestLoanRepositoryScheduleStructure(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	cust := customer.NewCustomer("Jane Doe", "1 Main St")
+	require.NoError(t, NewCustomerRepository(db, clock.System(), testLogger).Save(ctx, cust))
+	newLoan := &loan.Loan{
+		PrincipalAmount: 300, InterestRate: 0.1, TermWeeks: 3, WeeklyPaymentAmount: 80, TotalLoanAmount: 330,
+		StartDate: day("2025-01-06"), Status: loan.StatusActive,
+		Structure: loan.ScheduleStructure{Type: loan.ScheduleBalloon, BalloonAmount: 90},
+	}
+	schedule := []loan.ScheduleEntry{
+		{WeekNumber: 1, DueDate: day("2025-01-13"), DueAmount: 80, Status: loan.PaymentStatusPending},
+		{WeekNumber: 2, DueDate: day("2025-01-20"), DueAmount: 80, Status: loan.PaymentStatusPending},
+		{WeekNumber: 3, DueDate: day("2025-01-27"), DueAmount: 170, Status: loan.PaymentStatusPending},
+	}
+	repo := NewLoanRepository(db, clock.System(), testLogger)
+
+	created, err := repo.CreateLoan(ctx, cust.CustomerID, newLoan, schedule)
+	require.NoError(t, err)
+	assert.Equal(t, newLoan.Structure, created.Structure)
+
+	read, err := repo.GetLoanByID(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, newLoan.Structure, read.Structure)
+}
+
+func TestLoanRepositoryCreateLoanConflicts(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewLoanRepository(db, clock.System(), testLogger)
+	ctx := context.Background()
+	customerID, _ := createTestLoan(t, db, day("2025-01-06"), "ref-1")
+
+	_, err := repo.CreateLoan(ctx, customerID, &loan.Loan{
+		PrincipalAmount: 100, TermWeeks: 1, TotalLoanAmount: 100, StartDate: day("2025-01-06"), Status: loan.StatusActive,
+	}, nil)
+	assert.ErrorIs(t, err, apperrors.ErrConflict, "a customer holds one loan at a time")
+
+	var ids []int64
+	require.NoError(t, repo.StreamLoans(ctx, loan.LoanFilter{}, func(l *loan.Loan) error {
+		ids = append(ids, l.ID)
+		return nil
+	}))
+	assert.Len(t, ids, 1, "the loan of a failed link is rolled back")
+
+	ref := "ref-1"
+	cust := customer.NewCustomer("John Doe", "2 Main St")
+	require.NoError(t, NewCustomerRepository(db, clock.System(), testLogger).Save(ctx, cust))
+	_, err = repo.CreateLoan(ctx, cust.CustomerID, &loan.Loan{
+		PrincipalAmount: 100, TermWeeks: 1, TotalLoanAmount: 100, StartDate: day("2025-01-06"), Status: loan.StatusActive, ExternalRef: &ref,
+	}, nil)
+	assert.ErrorIs(t, err, apperrors.ErrAlreadyExists)
+}
+
+func TestLoanRepositoryCreateLoanLinksCustomer(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewLoanRepository(db, clock.System(), testLogger)
+	customers := NewCustomerRepository(db, clock.System(), testLogger)
+	ctx := context.Background()
+	customerID, first := createTestLoan(t, db, day("2025-01-06"), "")
+	next := func() *loan.Loan {
+		return &loan.Loan{PrincipalAmount: 100, TermWeeks: 1, TotalLoanAmount: 100, StartDate: day("2025-02-03"), Status: loan.StatusActive}
+	}
+
+	require.NoError(t, repo.WithinTransaction(ctx, func(tx loan.TxRepository) error {
+		return tx.UpdateLoanStatus(ctx, first.ID, loan.StatusPaidOff)
+	}))
+
+	second, err := repo.CreateLoan(ctx, customerID, next(), nil)
+	require.NoError(t, err, "a paid-off loan makes way for a new one")
+	cust, err := customers.FindByID(ctx, customerID)
+	require.NoError(t, err)
+	require.NotNil(t, cust.LoanID)
+	assert.Equal(t, second.ID, *cust.LoanID)
+
+	cust.Active = false
+	cust.LoanID = nil
+	require.NoError(t, customers.Save(ctx, cust))
+	cust, err = customers.FindByID(ctx, customerID)
+	require.NoError(t, err)
+	require.Nil(t, cust.LoanID)
+	_, err = repo.CreateLoan(ctx, customerID, next(), nil)
+	assert.ErrorIs(t, err, apperrors.ErrConflict, "inactive customers take no loans")
+
+	_, err = repo.CreateLoan(ctx, customerID+100, next(), nil)
+	assert.ErrorIs(t, err, apperrors.ErrConflict)
+
+	var ids []int64
+	require.NoError(t, repo.StreamLoans(ctx, loan.LoanFilter{}, func(l *loan.Loan) error {
+		ids = append(ids, l.ID)
+		return nil
+	}))
+	assert.Equal(t, []int64{first.ID, second.ID}, ids, "failed links leave no loan behind")
+}
+
+func TestLoanRepositoryTenantScope(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewLoanRepository(db, clock.System(), testLogger)
+	customers := NewCustomerRepository(db, clock.System(), testLogger)
+	tenantA := scope.WithTenant(context.Background(), "tenant-a")
+	tenantB := scope.WithTenant(context.Background(), "tenant-b")
+	ref := "ref-b"
+
+	cust := customer.NewCustomer("Bob Roe", "2 Main St")
+	require.NoError(t, customers.Save(tenantB, cust))
+	created, err := repo.CreateLoan(tenantB, cust.CustomerID, &loan.Loan{
+		PrincipalAmount: 100, TermWeeks: 1, TotalLoanAmount: 100, StartDate: day("2025-01-06"), Status: loan.StatusActive, ExternalRef: &ref,
+	}, nil)
+	require.NoError(t, err)
+
+	_, err = repo.GetLoanByID(tenantA, created.ID)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound, "a tenant-a caller cannot read a tenant-b loan")
+	_, err = repo.GetLoanByPublicID(tenantA, created.PublicID)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	_, err = repo.GetLoanByExternalRef(tenantA, ref)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	_, err = repo.GetLastModified(tenantA, created.ID)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	_, err = customers.FindByID(tenantA, cust.CustomerID)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	assert.ErrorIs(t, customers.SetActiveStatus(tenantA, cust.CustomerID, false), apperrors.ErrNotFound)
+	listed, err := customers.FindAll(tenantA, customer.ListFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, listed)
+	streamed := 0
+	require.NoError(t, repo.StreamLoans(tenantA, loan.LoanFilter{}, func(*loan.Loan) error {
+		streamed++
+		return nil
+	}))
+	assert.Zero(t, streamed)
+
+	_, err = repo.CreateLoan(tenantA, cust.CustomerID, &loan.Loan{
+		PrincipalAmount: 100, TermWeeks: 1, TotalLoanAmount: 100, StartDate: day("2025-01-06"), Status: loan.StatusActive,
+	}, nil)
+	assert.ErrorIs(t, err, apperrors.ErrConflict, "a loan is not linked to another tenant's customer")
+
+	got, err := repo.GetLoanByID(tenantB, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, got.ID)
+	_, err = repo.GetLoanByID(context.Background(), created.ID)
+	assert.NoError(t, err, "unscoped callers such as batch jobs reach every tenant")
+}
+
+func TestLoanRepositoryDaysPastDue(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewLoanRepository(db, clock.System(), testLogger)
+	ctx := context.Background()
+	createTestLoan(t, db, day("2025-01-06"), "")
+	_, overdue := createTestLoan(t, db, day("2025-01-06"), "")
+
+	require.NoError(t, repo.UpdateDaysPastDue(ctx, overdue.ID, 35))
+	stored, err := repo.GetLoanByID(ctx, overdue.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 35, stored.DaysPastDue)
+
+	require.NoError(t, repo.UpdateDaysPastDue(ctx, overdue.ID, 35))
+	unchanged, err := repo.GetLoanByID(ctx, overdue.ID)
+	require.NoError(t, err)
+	assert.Equal(t, stored.UpdatedAt, unchanged.UpdatedAt, "an unchanged value leaves the row alone")
+
+	var ids []int64
+	require.NoError(t, repo.StreamLoans(ctx, loan.LoanFilter{MinDaysPastDue: 30}, func(l *loan.Loan) error {
+		ids = append(ids, l.ID)
+		return nil
+	}))
+	assert.Equal(t, []int64{overdue.ID}, ids)
+
+	require.NoError(t, repo.WithinTransaction(ctx, func(tx loan.TxRepository) error {
+		return tx.UpdateLoanStatus(ctx, overdue.ID, loan.StatusPaidOff)
+	}))
+	ids = nil
+	require.NoError(t, repo.StreamLoans(ctx, loan.LoanFilter{Status: loan.StatusPaidOff}, func(l *loan.Loan) error {
+		ids = append(ids, l.ID)
+		return nil
+	}))
+	assert.Equal(t, []int64{overdue.ID}, ids)
+	paidOff, err := repo.GetLoanByID(ctx, overdue.ID)
+	require.NoError(t, err)
+	assert.Zero(t, paidOff.DaysPastDue, "paying a loan off clears its days past due")
+}
+
+func TestLoanRepositoryPaymentTransaction(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewLoanRepository(db, clock.System(), testLogger)
+	ctx := context.Background()
+	_, created := createTestLoan(t, db, day("2025-01-06"), "")
+
+	for week := 1; week <= 3; week++ {
+		require.NoError(t, repo.WithinTransaction(ctx, func(tx loan.TxRepository) error {
+			require.NoError(t, tx.LockLoanForPayment(ctx, created.ID))
+			entry, err := tx.FindOldestUnpaidEntryForUpdate(ctx, created.ID)
+			require.NoError(t, err)
+			assert.Equal(t, week, entry.WeekNumber)
+
+			paidAt := time.Now()
+			entry.Status = loan.PaymentStatusPaid
+			entry.PaidAmount = entry.DueAmount
+			entry.PaymentDate = &paidAt
+			require.NoError(t, tx.UpdateScheduleEntry(ctx, entry))
+
+			allPaid, err := tx.CheckIfAllPaymentsMade(ctx, created.ID)
+			require.NoError(t, err)
+			assert.Equal(t, week == 3, allPaid)
+			if allPaid {
+				return tx.UpdateLoanStatus(ctx, created.ID, loan.StatusPaidOff)
+			}
+			return nil
+		}))
+	}
+
+	err := repo.WithinTransaction(ctx, func(tx loan.TxRepository) error {
+		_, err := tx.FindOldestUnpaidEntryForUpdate(ctx, created.ID)
+		return err
+	})
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+
+	rolledBack := errors.New("give up")
+	err = repo.WithinTransaction(ctx, func(tx loan.TxRepository) error {
+		require.NoError(t, tx.UpdateLoanStatus(ctx, created.ID, loan.StatusActive))
+		return rolledBack
+	})
+	assert.ErrorIs(t, err, rolledBack)
+
+	paidOff, err := repo.GetLoanByID(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, loan.StatusPaidOff, paidOff.Status)
+
+	schedule, err := repo.GetScheduleByLoanID(ctx, created.ID)
+	require.NoError(t, err)
+	require.NotNil(t, schedule[2].PaymentDate)
+
+	lastModified, err := repo.GetLastModified(ctx, created.ID)
+	require.NoError(t, err)
+	assert.False(t, lastModified.Before(schedule[2].UpdatedAt))
+
+	active, err := repo.GetActiveLoanTenants(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, active)
+}
+
+func TestLoanRepositoryInstallmentSummary(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewLoanRepository(db, clock.System(), testLogger)
+	ctx := context.Background()
+	_, created := createTestLoan(t, db, day("2025-01-06"), "")
+	assert.Equal(t, day("2025-01-13"), *created.NextDueDate)
+	assert.Equal(t, 110.0, created.NextDueAmount)
+	assert.Equal(t, 0, created.InstallmentsPaid)
+	assert.Equal(t, 3, created.InstallmentsRemaining)
+
+	pay := func(amount loan.Money) {
+		require.NoError(t, repo.WithinTransaction(ctx, func(tx loan.TxRepository) error {
+			entry, err := tx.FindOldestUnpaidEntryForUpdate(ctx, created.ID)
+			require.NoError(t, err)
+			entry.PaidAmount += amount
+			if entry.PaidAmount >= entry.DueAmount {
+				entry.Status = loan.PaymentStatusPaid
+			}
+			return tx.UpdateScheduleEntry(ctx, entry)
+		}))
+	}
+	summary := func() *loan.Loan {
+		l, err := repo.GetLoanByID(ctx, created.ID)
+		require.NoError(t, err)
+		return l
+	}
+
+	pay(110)
+	pay(40)
+	l := summary()
+	assert.Equal(t, day("2025-01-20"), *l.NextDueDate)
+	assert.Equal(t, 70.0, l.NextDueAmount, "what is left on a partly paid installment")
+	assert.Equal(t, 1, l.InstallmentsPaid)
+	assert.Equal(t, 2, l.InstallmentsRemaining)
+
+	// Writes made outside the repository are picked up too.
+	_, err := db.ExecContext(ctx, `DELETE FROM loan_schedule WHERE loan_id = $1 AND week_number = 3`, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, summary().InstallmentsRemaining)
+
+	pay(70)
+	l = summary()
+	assert.Nil(t, l.NextDueDate)
+	assert.Equal(t, 0.0, l.NextDueAmount)
+	assert.Equal(t, 2, l.InstallmentsPaid)
+	assert.Equal(t, 0, l.InstallmentsRemaining)
+}
+
+func TestLoanRepositoryScheduleRebuild(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewLoanRepository(db, clock.System(), testLogger)
+	ctx := context.Background()
+	_, created := createTestLoan(t, db, day("2025-01-06"), "")
+
+	// Drift week 2, lose week 3 and add a week past the term.
+	_, err := db.ExecContext(ctx, `UPDATE loan_schedule SET due_amount = 99, due_date = '2025-01-22' WHERE loan_id = $1 AND week_number = 2`, created.ID)
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, `UPDATE loan_schedule SET week_number = 4, due_date = '2025-02-03' WHERE loan_id = $1 AND week_number = 3`, created.ID)
+	require.NoError(t, err)
+
+	require.NoError(t, repo.WithinTransaction(ctx, func(tx loan.TxRepository) error {
+		current, err := tx.GetScheduleForUpdate(ctx, created.ID)
+		require.NoError(t, err)
+		plan, err := loan.PlanScheduleRebuild(created, current)
+		require.NoError(t, err)
+		require.Len(t, plan.Changes, 3)
+		return tx.ApplyScheduleChanges(ctx, created.ID, plan.Changes)
+	}))
+
+	schedule, err := repo.GetScheduleByLoanID(ctx, created.ID)
+	require.NoError(t, err)
+	require.Len(t, schedule, 3)
+	for i, entry := range schedule {
+		assert.Equal(t, i+1, entry.WeekNumber)
+		assert.Equal(t, day("2025-01-06").AddDate(0, 0, 7*(i+1)), entry.DueDate)
+		assert.Equal(t, 110.0, entry.DueAmount)
+	}
+}
+
+func TestLoanRepositoryRepricing(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewLoanRepository(db, clock.System(), testLogger)
+	ctx := context.Background()
+	_, created := createTestLoan(t, db, day("2025-01-06"), "")
+	admin := "admin"
+
+	require.NoError(t, repo.WithinTransaction(ctx, func(tx loan.TxRepository) error {
+		history, err := tx.GetRateHistory(ctx, created.ID)
+		require.NoError(t, err)
+		assert.Empty(t, history)
+		current, err := tx.GetScheduleForUpdate(ctx, created.ID)
+		require.NoError(t, err)
+		plan, err := loan.PlanRepricing(created, current, 0.16, day("2025-01-20"))
+		require.NoError(t, err)
+		plan.Change.ChangedBy = &admin
+		plan.Change.CreatedAt = time.Now()
+		require.NoError(t, tx.ApplyScheduleChanges(ctx, created.ID, plan.Changes))
+		require.NoError(t, tx.RepriceLoan(ctx, plan))
+		assert.NotZero(t, plan.Change.ID)
+		return nil
+	}))
+
+	repriced, err := repo.GetLoanByID(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0.16, repriced.InterestRate)
+	assert.Equal(t, 116.0, repriced.WeeklyPaymentAmount)
+	assert.Equal(t, 342.0, repriced.TotalLoanAmount)
+
+	history, err := repo.GetRateHistory(ctx, created.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, 0.1, history[0].PreviousRate)
+	assert.Equal(t, day("2025-01-20"), history[0].EffectiveFrom)
+	assert.Equal(t, "admin", *history[0].ChangedBy)
+
+	repriced.RateHistory = history
+	schedule, err := repo.GetScheduleByLoanID(ctx, created.ID)
+	require.NoError(t, err)
+	assert.NoError(t, loan.CheckScheduleInvariants(repriced, schedule))
+}
+
+func TestLoanRepositoryScheduleAdjustments(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewLoanRepository(db, clock.System(), testLogger)
+	ctx := context.Background()
+	_, created := createTestLoan(t, db, day("2025-01-06"), "")
+	admin := "admin"
+	today := day("2025-01-10")
+
+	var plan *loan.AdjustmentPlan
+	require.NoError(t, repo.WithinTransaction(ctx, func(tx loan.TxRepository) error {
+		adjustments, err := tx.GetScheduleAdjustments(ctx, created.ID)
+		require.NoError(t, err)
+		assert.Empty(t, adjustments)
+		current, err := tx.GetScheduleForUpdate(ctx, created.ID)
+		require.NoError(t, err)
+		plan, err = loan.PlanScheduleAdjustment(created, current, loan.ScheduleAdjustment{
+			Kind: loan.AdjustmentZeroInterest, StartsOn: day("2025-01-20"), Weeks: 1, Reason: "promotion",
+		}, today)
+		require.NoError(t, err)
+		plan.Adjustment.AppliedBy = &admin
+		plan.Adjustment.AppliedAt = time.Now()
+		require.NoError(t, tx.ApplyScheduleChanges(ctx, created.ID, plan.Changes))
+		require.NoError(t, tx.AddScheduleAdjustment(ctx, plan))
+		assert.NotZero(t, plan.Adjustment.ID)
+		return nil
+	}))
+
+	adjusted, err := repo.GetLoanByID(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 320.0, adjusted.TotalLoanAmount)
+	adjustments, err := repo.GetScheduleAdjustments(ctx, created.ID)
+	require.NoError(t, err)
+	require.Len(t, adjustments, 1)
+	assert.Equal(t, loan.AdjustmentZeroInterest, adjustments[0].Kind)
+	assert.Equal(t, day("2025-01-20"), adjustments[0].StartsOn)
+	assert.Equal(t, "admin", *adjustments[0].AppliedBy)
+	assert.Nil(t, adjustments[0].RemovedAt)
+
+	adjusted.Adjustments = adjustments
+	schedule, err := repo.GetScheduleByLoanID(ctx, created.ID)
+	require.NoError(t, err)
+	assert.NoError(t, loan.CheckScheduleInvariants(adjusted, schedule))
+
+	require.NoError(t, repo.WithinTransaction(ctx, func(tx loan.TxRepository) error {
+		removal, err := loan.PlanAdjustmentRemoval(adjusted, schedule, plan.Adjustment.ID, today)
+		require.NoError(t, err)
+		removedAt := time.Now()
+		removal.Adjustment.RemovedBy = &admin
+		removal.Adjustment.RemovedAt = &removedAt
+		require.NoError(t, tx.ApplyScheduleChanges(ctx, created.ID, removal.Changes))
+		require.NoError(t, tx.RemoveScheduleAdjustment(ctx, removal))
+		assert.ErrorIs(t, tx.RemoveScheduleAdjustment(ctx, removal), apperrors.ErrNotFound)
+		return nil
+	}))
+
+	restored, err := repo.GetLoanByID(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 330.0, restored.TotalLoanAmount)
+	adjustments, err = repo.GetScheduleAdjustments(ctx, created.ID)
+	require.NoError(t, err)
+	require.Len(t, adjustments, 1)
+	assert.NotNil(t, adjustments[0].RemovedAt)
+	restored.Adjustments = adjustments
+	schedule, err = repo.GetScheduleByLoanID(ctx, created.ID)
+	require.NoError(t, err)
+	assert.NoError(t, loan.CheckScheduleInvariants(restored, schedule))
+}
+
+func TestLoanRepositoryPrepayments(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewLoanRepository(db, clock.System(), testLogger)
+	ctx := context.Background()
+	_, created := createTestLoan(t, db, day("2025-01-06"), "")
+	teller := "teller"
+	reference := "TRF-7"
+	paidAt := day("2025-01-14").Add(9 * time.Hour)
+
+	prepay := func(amount loan.Money) error {
+		return repo.WithinTransaction(ctx, func(tx loan.TxRepository) error {
+			var err error
+			if created.Prepayments, err = tx.GetPrepayments(ctx, created.ID); err != nil {
+				return err
+			}
+			current, err := tx.GetScheduleForUpdate(ctx, created.ID)
+			require.NoError(t, err)
+			plan, err := loan.PlanPrepayment(created, current, amount, loan.PrepaymentReduceTerm, paidAt)
+			if err != nil {
+				return err
+			}
+			plan.Prepayment.Channel = loan.ChannelBankTransfer
+			plan.Prepayment.Reference = &reference
+			plan.Prepayment.RecordedBy = &teller
+			plan.Prepayment.PaidAt = paidAt
+			require.NoError(t, tx.ApplyScheduleChanges(ctx, created.ID, plan.Changes))
+			if err := tx.RecordPrepayment(ctx, plan); err != nil {
+				return err
+			}
+			assert.NotZero(t, plan.Prepayment.ID)
+			return nil
+		})
+	}
+
+	require.NoError(t, repo.WithinTransaction(ctx, func(tx loan.TxRepository) error {
+		entry, err := tx.FindOldestUnpaidEntryForUpdate(ctx, created.ID)
+		require.NoError(t, err)
+		paid := day("2025-01-13")
+		entry.Status = loan.PaymentStatusPaid
+		entry.PaidAmount = entry.DueAmount
+		entry.PaymentDate = &paid
+		return tx.UpdateScheduleEntry(ctx, entry)
+	}))
+	require.NoError(t, prepay(100))
+
+	reamortized, err := repo.GetLoanByID(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, reamortized.TermWeeks)
+	assert.Equal(t, 110.0, reamortized.WeeklyPaymentAmount)
+	assert.Equal(t, 320.0, reamortized.TotalLoanAmount)
+	prepayments, err := repo.GetPrepayments(ctx, created.ID)
+	require.NoError(t, err)
+	require.Len(t, prepayments, 1)
+	p := prepayments[0]
+	assert.Equal(t, loan.PrepaymentReduceTerm, p.Option)
+	assert.Equal(t, 1, p.AfterWeek)
+	assert.Equal(t, 3, p.PreviousTermWeeks)
+	assert.Equal(t, "TRF-7", *p.Reference)
+	assert.Equal(t, "teller", *p.RecordedBy)
+	assert.True(t, paidAt.Equal(p.PaidAt))
+
+	reamortized.Prepayments = prepayments
+	schedule, err := repo.GetScheduleByLoanID(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Len(t, schedule, 2)
+	assert.NoError(t, loan.CheckScheduleInvariants(reamortized, schedule))
+
+	created = reamortized
+	assert.ErrorIs(t, prepay(50), apperrors.ErrAlreadyExists, "references are unique per channel")
+	prepayments, err = repo.GetPrepayments(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Len(t, prepayments, 1)
+
+	totals, err := repo.SumPaymentsByChannel(ctx, day("2025-01-14"), day("2025-01-15"))
+	require.NoError(t, err)
+	assert.Equal(t, []loan.ChannelCollections{{Channel: loan.ChannelBankTransfer, Payments: 1, Amount: 100}}, totals)
+}
+
+func TestLoanRepositoryHolds(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewLoanRepository(db, clock.System(), testLogger)
+	ctx := context.Background()
+	_, created := createTestLoan(t, db, day("2025-01-06"), "")
+	admin := "admin"
+
+	none, err := repo.GetActiveHold(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Nil(t, none)
+	before, err := repo.GetLastModified(ctx, created.ID)
+	require.NoError(t, err)
+
+	hold := &loan.Hold{LoanID: created.ID, Reason: "disputed", PlacedBy: &admin, PlacedAt: before.Add(time.Minute)}
+	require.NoError(t, repo.PlaceHold(ctx, hold))
+	assert.NotZero(t, hold.ID)
+	again := *hold
+	assert.ErrorIs(t, repo.PlaceHold(ctx, &again), apperrors.ErrConflict)
+	missing := *hold
+	missing.LoanID = created.ID + 100
+	assert.ErrorIs(t, repo.PlaceHold(ctx, &missing), apperrors.ErrNotFound)
+
+	var active *loan.Hold
+	require.NoError(t, repo.WithinTransaction(ctx, func(tx loan.TxRepository) (err error) {
+		active, err = tx.GetActiveHold(ctx, created.ID)
+		return err
+	}))
+	require.NotNil(t, active)
+	assert.Equal(t, "disputed", active.Reason)
+	assert.Equal(t, "admin", *active.PlacedBy)
+
+	lastModified, err := repo.GetLastModified(ctx, created.ID)
+	require.NoError(t, err)
+	assert.True(t, lastModified.After(before), "placing a hold changes the loan's ETag")
+
+	released, err := repo.ReleaseHold(ctx, created.ID, &admin, before.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, hold.ID, released.ID)
+	require.NotNil(t, released.ReleasedAt)
+	_, err = repo.ReleaseHold(ctx, created.ID, &admin, before.Add(time.Hour))
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+
+	none, err = repo.GetActiveHold(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Nil(t, none)
+	require.NoError(t, repo.PlaceHold(ctx, &loan.Hold{LoanID: created.ID, Reason: "again", PlacedAt: before.Add(2 * time.Hour)}),
+		"a released hold does not block a new one")
+}
+
+func TestLoanRepositoryPaymentsLedger(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewLoanRepository(db, clock.System(), testLogger)
+	ctx := context.Background()
+	_, created := createTestLoan(t, db, day("2025-01-06"), "")
+	schedule, err := repo.GetScheduleByLoanID(ctx, created.ID)
+	require.NoError(t, err)
+
+	record := func(scheduleID int64, channel loan.PaymentChannel, reference string, paidAt time.Time) error {
+		payment := &loan.Payment{LoanID: created.ID, ScheduleID: &scheduleID, Amount: 110, Channel: channel, PaidAt: paidAt}
+		if reference != "" {
+			payment.Reference = &reference
+		}
+		return repo.WithinTransaction(ctx, func(tx loan.TxRepository) error {
+			if err := tx.RecordPayment(ctx, payment); err != nil {
+				return err
+			}
+			assert.NotZero(t, payment.ID)
+			return nil
+		})
+	}
+
+	require.NoError(t, record(schedule[0].ID, loan.ChannelCash, "", day("2025-01-13").Add(9*time.Hour)))
+	require.NoError(t, record(schedule[1].ID, loan.ChannelCash, "", day("2025-01-13").Add(23*time.Hour)))
+	require.NoError(t, record(schedule[2].ID, loan.ChannelBankTransfer, "TRF-1", day("2025-01-14")))
+	assert.ErrorIs(t, record(schedule[2].ID, loan.ChannelBankTransfer, "TRF-1", day("2025-01-14")), apperrors.ErrAlreadyExists)
+	require.NoError(t, record(schedule[2].ID, loan.ChannelGateway, "TRF-1", day("2025-01-14")), "references are unique per channel")
+
+	totals, err := repo.SumPaymentsByChannel(ctx, day("2025-01-13"), day("2025-01-14"))
+	require.NoError(t, err)
+	assert.Equal(t, []loan.ChannelCollections{{Channel: loan.ChannelCash, Payments: 2, Amount: 220}}, totals)
+
+	totals, err = repo.SumPaymentsByChannel(ctx, day("2025-01-13"), day("2025-01-15"))
+	require.NoError(t, err)
+	assert.Len(t, totals, 3)
+
+	latest, err := repo.ListPayments(ctx, created.ID, 2)
+	require.NoError(t, err)
+	require.Len(t, latest, 2)
+	assert.Equal(t, loan.ChannelGateway, latest[0].Channel, "newest first, ties by ID")
+	assert.Equal(t, "TRF-1", *latest[1].Reference)
+	assert.True(t, latest[1].PaidAt.Equal(day("2025-01-14")))
+}
+
+func TestLoanRepositorySearchLoans(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewLoanRepository(db, clock.System(), testLogger)
+	ctx := context.Background()
+	customerID, created := createTestLoan(t, db, day("2025-01-06"), "")
+	schedule, err := repo.GetScheduleByLoanID(ctx, created.ID)
+	require.NoError(t, err)
+
+	reference := "TRF-1"
+	require.NoError(t, repo.WithinTransaction(ctx, func(tx loan.TxRepository) error {
+		if err := tx.RecordPayment(ctx, &loan.Payment{LoanID: created.ID, ScheduleID: &schedule[0].ID, Amount: 110, Channel: loan.ChannelCash, PaidAt: day("2025-01-13")}); err != nil {
+			return err
+		}
+		return tx.RecordPayment(ctx, &loan.Payment{LoanID: created.ID, ScheduleID: &schedule[1].ID, Amount: 109.5, Channel: loan.ChannelBankTransfer, Reference: &reference, PaidAt: day("2025-01-20")})
+	}))
+	_, err = db.ExecContext(ctx, `
+        INSERT INTO prepayments (loan_id, option, amount, after_week, principal, weekly_principal, previous_term_weeks,
+            previous_weekly_payment_amount, previous_total_loan_amount, term_weeks, weekly_payment_amount, total_loan_amount,
+            channel, reference, paid_at)
+        VALUES ($1, 'REDUCE_TERM', 50, 2, 50, 50, 3, 110, 330, 3, 110, 280, 'GATEWAY', 'PAY-9', $2)`,
+		created.ID, day("2025-01-21").UTC())
+	require.NoError(t, err)
+
+	window := func(q loan.LoanSearch) loan.LoanSearch {
+		q.From, q.To, q.Limit = day("2025-01-01"), day("2025-02-01"), 10
+		return q
+	}
+	amount, tolerance := loan.Money(110), loan.Money(1)
+
+	matches, err := repo.SearchLoans(ctx, loan.LoanSearch{Reference: "TRF-1", Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	assert.Equal(t, created.ID, matches[0].LoanID)
+	assert.Equal(t, created.PublicID, matches[0].LoanPublicID)
+	assert.Equal(t, customerID, *matches[0].CustomerID)
+	assert.Equal(t, "Jane Doe", *matches[0].CustomerName)
+	require.NotNil(t, matches[0].Payment)
+	assert.Equal(t, loan.ChannelBankTransfer, matches[0].Payment.Channel)
+	assert.True(t, matches[0].Payment.PaidAt.Equal(day("2025-01-20")))
+	assert.False(t, matches[0].Payment.Prepayment)
+
+	matches, err = repo.SearchLoans(ctx, loan.LoanSearch{Reference: "PAY-9", Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	assert.True(t, matches[0].Payment.Prepayment, "prepayments are searched too")
+
+	matches, err = repo.SearchLoans(ctx, window(loan.LoanSearch{Amount: &amount, Tolerance: &tolerance}))
+	require.NoError(t, err)
+	require.Len(t, matches, 2)
+	assert.Equal(t, 109.5, matches[0].Payment.Amount, "newest payment first")
+
+	matches, err = repo.SearchLoans(ctx, loan.LoanSearch{Amount: &amount, Tolerance: &tolerance, From: day("2025-01-14"), To: day("2025-01-21"), Limit: 10})
+	require.NoError(t, err)
+	assert.Len(t, matches, 1, "the window ends before to")
+
+	matches, err = repo.SearchLoans(ctx, window(loan.LoanSearch{Amount: &amount, Tolerance: &tolerance, CustomerName: "john"}))
+	require.NoError(t, err)
+	assert.Empty(t, matches, "criteria must all match")
+
+	matches, err = repo.SearchLoans(ctx, loan.LoanSearch{CustomerName: "ANE D", Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	assert.Nil(t, matches[0].Payment)
+	assert.Equal(t, loan.StatusActive, matches[0].Status)
+
+	matches, err = repo.SearchLoans(ctx, loan.LoanSearch{CustomerName: "J%e", Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, matches, "wildcards in the name are taken literally")
+}
+
+func TestLoanRepositorySnapshots(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewLoanRepository(db, clock.System(), testLogger)
+	ctx := context.Background()
+	start := time.Now().UTC().AddDate(0, 0, -30)
+	_, created := createTestLoan(t, db, start, "")
+
+	asOf := time.Now().UTC()
+	written, err := repo.WriteDailySnapshots(ctx, asOf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), written)
+	written, err = repo.WriteDailySnapshots(ctx, asOf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), written, "a second run overwrites the day")
+
+	latest, ok, err := repo.LatestSnapshotDate(ctx, asOf.AddDate(0, 0, 1))
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, asOf.Format(time.DateOnly), latest.Format(time.DateOnly))
+
+	_, ok, err = repo.LatestSnapshotDate(ctx, asOf.AddDate(0, 0, -1))
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	snapshots, err := repo.GetSnapshots(ctx, created.ID, asOf.AddDate(0, 0, -7), asOf)
+	require.NoError(t, err)
+	require.Len(t, snapshots, 1)
+	assert.InDelta(t, 330, snapshots[0].Outstanding, 0.001)
+	assert.Equal(t, 23, snapshots[0].DPD, "counted from the first installment, due a week after the start")
+
+	rows, err := repo.GetPortfolioRows(ctx, latest)
+	require.NoError(t, err)
+	assert.Equal(t, []loan.PortfolioRow{{Status: loan.StatusActive, DPD: 23, Loans: 1, Outstanding: 330}}, rows)
+
+	var streamed []loan.Snapshot
+	require.NoError(t, repo.StreamSnapshots(ctx, latest, 0, func(s loan.Snapshot) error {
+		streamed = append(streamed, s)
+		return nil
+	}))
+	assert.Equal(t, snapshots, streamed)
+}
+
+func TestLoanRepositoryFees(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewLoanRepository(db, clock.System(), testLogger)
+	ctx := context.Background()
+	_, created := createTestLoan(t, db, day("2025-01-06"), "")
+	teller, supervisor := "teller", "supervisor"
+	postedAt := day("2025-01-14").Add(9 * time.Hour)
+
+	fee := &loan.Fee{LoanID: created.ID, Type: loan.FeeBounce, Amount: 25, Reason: "direct debit returned", PostedBy: &teller, PostedAt: postedAt}
+	require.NoError(t, repo.PostFee(ctx, fee))
+	assert.NotZero(t, fee.ID)
+	assert.ErrorIs(t, repo.PostFee(ctx, &loan.Fee{LoanID: created.ID + 1, Type: loan.FeeLegal, Amount: 50, Reason: "court filing", PostedAt: postedAt}),
+		apperrors.ErrNotFound)
+
+	items, err := repo.ListBalanceItems(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []loan.BalanceItem{{Kind: loan.BalanceFee, Amount: 25, Description: "Bounce fee: direct debit returned"}}, items)
+
+	request := func(reason string) (*loan.FeeWaiver, error) {
+		w := &loan.FeeWaiver{FeeID: fee.ID, LoanID: created.ID, Reason: reason, Status: loan.WaiverPending, RequestedBy: &teller, RequestedAt: postedAt}
+		return w, repo.RequestFeeWaiver(ctx, w)
+	}
+	decide := func(w *loan.FeeWaiver, status loan.WaiverStatus) error {
+		decidedAt := postedAt.Add(time.Hour)
+		w.Status, w.DecidedBy, w.DecidedAt = status, &supervisor, &decidedAt
+		return repo.DecideFeeWaiver(ctx, w)
+	}
+
+	rejected, err := request("bank error")
+	require.NoError(t, err)
+	_, err = request("bank error")
+	assert.ErrorIs(t, err, apperrors.ErrConflict, "one waiver pending at a time")
+	require.NoError(t, decide(rejected, loan.WaiverRejected))
+	assert.ErrorIs(t, decide(rejected, loan.WaiverApproved), apperrors.ErrConflict)
+
+	approved, err := request("bank confirmed error")
+	require.NoError(t, err)
+	require.NoError(t, decide(approved, loan.WaiverApproved))
+	_, err = request("again")
+	assert.ErrorIs(t, err, apperrors.ErrConflict)
+	assert.ErrorIs(t, repo.RequestFeeWaiver(ctx, &loan.FeeWaiver{FeeID: fee.ID, LoanID: created.ID + 1, Reason: "x", Status: loan.WaiverPending, RequestedAt: postedAt}),
+		apperrors.ErrNotFound)
+
+	fees, err := repo.GetFees(ctx, created.ID)
+	require.NoError(t, err)
+	require.Len(t, fees, 1)
+	require.Len(t, fees[0].Waivers, 2)
+	assert.Equal(t, loan.WaiverRejected, fees[0].Waivers[0].Status)
+	assert.Equal(t, "supervisor", *fees[0].Waivers[1].DecidedBy)
+	assert.True(t, postedAt.Equal(fees[0].PostedAt))
+	assert.True(t, fees[0].Waived())
+
+	items, err = repo.ListBalanceItems(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Empty(t, items, "a waived fee is no longer owed")
+}
+
+func TestLoanRepositoryFeePayments(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewLoanRepository(db, clock.System(), testLogger)
+	ctx := context.Background()
+	_, created := createTestLoan(t, db, day("2025-01-06"), "")
+	postedAt := day("2025-01-14").Add(9 * time.Hour)
+	fee := &loan.Fee{LoanID: created.ID, Type: loan.FeeLegal, Amount: 50, Reason: "court filing", PostedAt: postedAt,
+		Tax: &loan.TaxLine{Jurisdiction: "ID", Rate: 0.11, TaxableAmount: 50, Amount: 5.5}}
+	require.NoError(t, repo.PostFee(ctx, fee))
+
+	for range 3 {
+		require.NoError(t, repo.WithinTransaction(ctx, func(tx loan.TxRepository) error {
+			entry, err := tx.FindOldestUnpaidEntryForUpdate(ctx, created.ID)
+			require.NoError(t, err)
+			entry.Status, entry.PaidAmount = loan.PaymentStatusPaid, entry.DueAmount
+			return tx.UpdateScheduleEntry(ctx, entry)
+		}))
+	}
+
+	require.NoError(t, repo.WithinTransaction(ctx, func(tx loan.TxRepository) error {
+		allPaid, err := tx.CheckIfAllPaymentsMade(ctx, created.ID)
+		require.NoError(t, err)
+		assert.False(t, allPaid, "the fee is still owed")
+
+		open, err := tx.FindOldestOpenFeeForUpdate(ctx, created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, fee.ID, open.ID)
+		assert.Equal(t, 55.5, open.Owed())
+
+		paidAt := postedAt.Add(time.Hour)
+		open.PaidAt = &paidAt
+		require.NoError(t, tx.SettleFee(ctx, open))
+		assert.ErrorIs(t, tx.SettleFee(ctx, open), apperrors.ErrConflict)
+		require.NoError(t, tx.RecordPayment(ctx, &loan.Payment{LoanID: created.ID, FeeID: &open.ID, Amount: 55.5, Channel: loan.ChannelCash, PaidAt: paidAt}))
+
+		allPaid, err = tx.CheckIfAllPaymentsMade(ctx, created.ID)
+		require.NoError(t, err)
+		assert.True(t, allPaid)
+		_, err = tx.FindOldestOpenFeeForUpdate(ctx, created.ID)
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+		return nil
+	}))
+
+	items, err := repo.ListBalanceItems(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Empty(t, items, "a paid fee is no longer owed")
+
+	fees, err := repo.GetFees(ctx, created.ID)
+	require.NoError(t, err)
+	require.NotNil(t, fees[0].PaidAt)
+
+	payments, err := repo.ListPayments(ctx, created.ID, 5)
+	require.NoError(t, err)
+	require.Len(t, payments, 1)
+	assert.Nil(t, payments[0].ScheduleID)
+	assert.Equal(t, fee.ID, *payments[0].FeeID)
+}
+
+func TestLoanRepositoryTaxLines(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewLoanRepository(db, clock.System(), testLogger)
+	ctx := context.Background()
+	_, created := createTestLoan(t, db, day("2025-01-06"), "")
+	postedAt := day("2025-01-14").Add(9 * time.Hour)
+	post := func(feeType loan.FeeType, amount, tax loan.Money) *loan.Fee {
+		fee := &loan.Fee{LoanID: created.ID, Type: feeType, Amount: amount, Reason: "charged", PostedAt: postedAt,
+			Tax: &loan.TaxLine{LoanID: created.ID, Jurisdiction: "ID", Rate: 0.11, TaxableAmount: amount, Amount: tax, CreatedAt: postedAt}}
+		require.NoError(t, repo.PostFee(ctx, fee))
+		return fee
+	}
+	bounce := post(loan.FeeBounce, 25, 2.75)
+	post(loan.FeeLegal, 50, 5.5)
+	assert.NotZero(t, bounce.Tax.ID)
+	assert.Equal(t, bounce.ID, bounce.Tax.FeeID)
+
+	items, err := repo.ListBalanceItems(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []loan.BalanceItem{
+		{Kind: loan.BalanceFee, Amount: 25, Description: "Bounce fee: charged"},
+		{Kind: loan.BalanceTax, Amount: 2.75, Description: "Tax on Bounce fee: charged"},
+		{Kind: loan.BalanceFee, Amount: 50, Description: "Legal fee: charged"},
+		{Kind: loan.BalanceTax, Amount: 5.5, Description: "Tax on Legal fee: charged"},
+	}, items)
+
+	waiver := &loan.FeeWaiver{FeeID: bounce.ID, LoanID: created.ID, Reason: "bank error", Status: loan.WaiverPending, RequestedAt: postedAt}
+	require.NoError(t, repo.RequestFeeWaiver(ctx, waiver))
+	decidedAt := postedAt.Add(time.Hour)
+	waiver.Status, waiver.DecidedAt = loan.WaiverApproved, &decidedAt
+	require.NoError(t, repo.DecideFeeWaiver(ctx, waiver))
+
+	fees, err := repo.GetFees(ctx, created.ID)
+	require.NoError(t, err)
+	require.Len(t, fees, 2)
+	require.NotNil(t, fees[0].Tax)
+	assert.Equal(t, "ID", fees[0].Tax.Jurisdiction)
+	assert.Equal(t, 0.11, fees[0].Tax.Rate)
+	assert.Equal(t, 2.75, fees[0].Tax.Amount)
+	assert.True(t, postedAt.Equal(fees[0].Tax.CreatedAt))
+
+	items, err = repo.ListBalanceItems(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Len(t, items, 2, "the tax is waived with its fee")
+
+	totals, err := repo.SumTaxLines(ctx, day("2025-01-14"), day("2025-01-15"))
+	require.NoError(t, err)
+	assert.Equal(t, []loan.TaxTotals{
+		{Jurisdiction: "ID", FeeType: loan.FeeBounce, Lines: 1, TaxableAmount: 25, Amount: 2.75, WaivedAmount: 2.75},
+		{Jurisdiction: "ID", FeeType: loan.FeeLegal, Lines: 1, TaxableAmount: 50, Amount: 5.5},
+	}, totals)
+	totals, err = repo.SumTaxLines(ctx, day("2025-01-15"), day("2025-01-16"))
+	require.NoError(t, err)
+	assert.Empty(t, totals)
+}
@@ -0,0 +1,93 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"fmt"
+)
+
+const scheduleAdjustmentColumns = `id, loan_id, kind, starts_on, weeks, reason, applied_by, applied_at, removed_by, removed_at`
+
+func (r *LoanRepository) GetScheduleAdjustments(ctx context.Context, loanID int64) ([]loan.ScheduleAdjustment, error) {
+	return r.getScheduleAdjustments(ctx, r.db, loanID)
+}
+
+func (t *loanTx) GetScheduleAdjustments(ctx context.Context, loanID int64) ([]loan.ScheduleAdjustment, error) {
+	return t.r.getScheduleAdjustments(ctx, t.tx, loanID)
+}
+
+func (r *LoanRepository) getScheduleAdjustments(ctx context.Context, db rowsQuerier, loanID int64) ([]loan.ScheduleAdjustment, error) {
+	rows, err := db.QueryContext(ctx, `SELECT `+scheduleAdjustmentColumns+` FROM schedule_adjustments WHERE loan_id = $1 ORDER BY id`, loanID)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to read schedule adjustments", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	adjustments := []loan.ScheduleAdjustment{}
+	for rows.Next() {
+		var a loan.ScheduleAdjustment
+		if err := rows.Scan(&a.ID, &a.LoanID, &a.Kind, &a.StartsOn, &a.Weeks, &a.Reason, &a.AppliedBy, &a.AppliedAt, &a.RemovedBy, &a.RemovedAt); err != nil {
+			r.logger.ErrorContext(ctx, "Failed to scan schedule adjustment", "loan_id", loanID, "error", err)
+			return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+		}
+		adjustments = append(adjustments, a)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.ErrorContext(ctx, "Error iterating schedule adjustments", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	return adjustments, nil
+}
+
+func (t *loanTx) AddScheduleAdjustment(ctx context.Context, plan *loan.AdjustmentPlan) error {
+	a := &plan.Adjustment
+	if err := t.updateLoanTotal(ctx, a.LoanID, plan.TotalLoanAmount); err != nil {
+		return err
+	}
+	err := t.tx.QueryRowContext(ctx, `
+        INSERT INTO schedule_adjustments (loan_id, kind, starts_on, weeks, reason, applied_by, applied_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        RETURNING id`,
+		a.LoanID, a.Kind, dateArg(a.StartsOn), a.Weeks, a.Reason, a.AppliedBy, a.AppliedAt.UTC(),
+	).Scan(&a.ID)
+	if err != nil {
+		t.r.logger.ErrorContext(ctx, "Failed to insert schedule adjustment", "loan_id", a.LoanID, "error", err)
+		return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	return nil
+}
+
+func (t *loanTx) RemoveScheduleAdjustment(ctx context.Context, plan *loan.AdjustmentPlan) error {
+	a := &plan.Adjustment
+	var removedAt any
+	if a.RemovedAt != nil {
+		removedAt = a.RemovedAt.UTC()
+	}
+	res, err := t.tx.ExecContext(ctx, `UPDATE schedule_adjustments SET removed_by = $3, removed_at = $4
+        WHERE id = $1 AND loan_id = $2 AND removed_at IS NULL`, a.ID, a.LoanID, a.RemovedBy, removedAt)
+	if err != nil {
+		t.r.logger.ErrorContext(ctx, "Failed to remove schedule adjustment", "loan_id", a.LoanID, "adjustment_id", a.ID, "error", err)
+		return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	if n, _ := res.RowsAffected(); n != 1 {
+		return fmt.Errorf("%w: loan %d has no active adjustment %d", apperrors.ErrNotFound, a.LoanID, a.ID)
+	}
+	return t.updateLoanTotal(ctx, a.LoanID, plan.TotalLoanAmount)
+}
+
+func (t *loanTx) updateLoanTotal(ctx context.Context, loanID int64, total loan.Money) error {
+	res, err := t.tx.ExecContext(ctx, `UPDATE loans SET total_loan_amount = $1, updated_at = $2 WHERE id = $3`, total, now(t.r.clock), loanID)
+	if err != nil {
+		t.r.logger.ErrorContext(ctx, "Failed to update loan total", "loan_id", loanID, "error", err)
+		return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	if n, _ := res.RowsAffected(); n != 1 {
+		t.r.logger.ErrorContext(ctx, "Loan total update affected zero rows", "loan_id", loanID)
+		return fmt.Errorf("%w: loan total update affected zero rows", apperrors.ErrDatabase)
+	}
+	return nil
+}
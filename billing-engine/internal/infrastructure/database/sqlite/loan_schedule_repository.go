@@ -0,0 +1,92 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"fmt"
+)
+
+// GetScheduleForUpdate needs no row locks. SQLite allows one writer, and a
+// payment committed after this read makes the rebuild's first write fail
+// instead of being overwritten.
+func (t *loanTx) GetScheduleForUpdate(ctx context.Context, loanID int64) ([]loan.ScheduleEntry, error) {
+	rows, err := t.tx.QueryContext(ctx, `
+        SELECT `+scheduleColumns+`
+        FROM loan_schedule
+        WHERE loan_id = $1
+        ORDER BY week_number ASC`, loanID)
+	if err != nil {
+		t.r.logger.ErrorContext(ctx, "Failed to query loan schedule", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	schedule := make([]loan.ScheduleEntry, 0)
+	for rows.Next() {
+		var entry loan.ScheduleEntry
+		if err := scanScheduleEntry(rows, &entry); err != nil {
+			t.r.logger.ErrorContext(ctx, "Failed to scan schedule row", "loan_id", loanID, "error", err)
+			return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+		}
+		schedule = append(schedule, entry)
+	}
+	if err := rows.Err(); err != nil {
+		t.r.logger.ErrorContext(ctx, "Error iterating schedule rows", "loan_id", loanID, "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	return schedule, nil
+}
+
+func (t *loanTx) ApplyScheduleChanges(ctx context.Context, loanID int64, changes []loan.ScheduleChange) error {
+	updatedAt := now(t.r.clock)
+	for _, change := range changes {
+		var affected int64
+		switch change.Kind {
+		case loan.ScheduleEntryAdded:
+			entry := change.After
+			err := t.tx.QueryRowContext(ctx, `
+                INSERT INTO loan_schedule (loan_id, week_number, due_date, due_amount, status, created_at, updated_at)
+                VALUES ($1, $2, $3, $4, $5, $6, $6)
+                RETURNING id`,
+				loanID, entry.WeekNumber, dateArg(entry.DueDate), entry.DueAmount, entry.Status, updatedAt,
+			).Scan(&entry.ID)
+			if err != nil {
+				t.r.logger.ErrorContext(ctx, "Failed to insert schedule entry", "loan_id", loanID, "week", change.WeekNumber, "error", err)
+				return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+			}
+			entry.LoanID, entry.CreatedAt, entry.UpdatedAt = loanID, updatedAt, updatedAt
+			continue
+		case loan.ScheduleEntryUpdated:
+			entry := change.After
+			res, err := t.tx.ExecContext(ctx, `
+                UPDATE loan_schedule
+                SET due_date = $1, due_amount = $2, status = $3, updated_at = $4
+                WHERE id = $5 AND loan_id = $6`,
+				dateArg(entry.DueDate), entry.DueAmount, entry.Status, updatedAt, entry.ID, loanID)
+			if err != nil {
+				t.r.logger.ErrorContext(ctx, "Failed to reschedule entry", "loan_id", loanID, "entry_id", entry.ID, "error", err)
+				return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+			}
+			entry.UpdatedAt = updatedAt
+			affected, _ = res.RowsAffected()
+		case loan.ScheduleEntryRemoved:
+			res, err := t.tx.ExecContext(ctx, `DELETE FROM loan_schedule WHERE id = $1 AND loan_id = $2`, change.Before.ID, loanID)
+			if err != nil {
+				t.r.logger.ErrorContext(ctx, "Failed to delete schedule entry", "loan_id", loanID, "entry_id", change.Before.ID, "error", err)
+				return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+			}
+			affected, _ = res.RowsAffected()
+		default:
+			return fmt.Errorf("%w: unknown schedule change %q", apperrors.ErrInternalServer, change.Kind)
+		}
+		if affected != 1 {
+			t.r.logger.ErrorContext(ctx, "Schedule change affected no row", "loan_id", loanID, "week", change.WeekNumber, "kind", change.Kind)
+			return fmt.Errorf("%w: %s of week %d affected %d rows", apperrors.ErrDatabase, change.Kind, change.WeekNumber, affected)
+		}
+	}
+	t.r.logger.InfoContext(ctx, "Loan schedule rebuilt in DB", "loan_id", loanID, "changes", len(changes))
+	return nil
+}
@@ -0,0 +1,165 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/infrastructure/monitoring"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+var _ loan.SnapshotRepository = (*LoanRepository)(nil)
+
+// writeSnapshotsQuery is the SQLite form of the PostgreSQL statement: days
+// past due come from julianday and the scalar MAX replaces GREATEST.
+const writeSnapshotsQuery = `
+        INSERT INTO loan_status_snapshots (snapshot_date, loan_id, status, outstanding, dpd, created_at)
+        SELECT $1, l.id, l.status,
+               MAX(COALESCE(SUM(s.due_amount - s.paid_amount) FILTER (WHERE s.status != 'PAID'), 0), 0),
+               COALESCE(CAST(julianday($1) - julianday(MIN(s.due_date) FILTER (WHERE s.status != 'PAID' AND s.due_date < $1)) AS INTEGER), 0),
+               $2
+        FROM loans l
+        LEFT JOIN loan_schedule s ON s.loan_id = l.id
+        WHERE l.created_at < date($1, '+1 day')
+        GROUP BY l.id, l.status
+        ON CONFLICT (loan_id, snapshot_date) DO UPDATE
+        SET status = excluded.status, outstanding = excluded.outstanding, dpd = excluded.dpd, created_at = excluded.created_at`
+
+const snapshotColumns = `loan_id, snapshot_date, status, outstanding, dpd`
+
+func (r *LoanRepository) WriteDailySnapshots(ctx context.Context, date time.Time) (int64, error) {
+	start := time.Now()
+	logCtx := r.logger.With(slog.String("operation", "WriteDailySnapshots"), slog.String("date", dateArg(date)))
+
+	res, err := r.db.ExecContext(ctx, writeSnapshotsQuery, dateArg(date), now(r.clock))
+	if err != nil {
+		monitoring.RecordDBQuery("WriteDailySnapshots", "error", time.Since(start))
+		logCtx.ErrorContext(ctx, "Failed to write loan snapshots", slog.Any("error", err))
+		return 0, fmt.Errorf("%w: failed to write loan snapshots: %w", apperrors.ErrDatabase, err)
+	}
+	monitoring.RecordDBQuery("WriteDailySnapshots", "success", time.Since(start))
+	return res.RowsAffected()
+}
+
+func (r *LoanRepository) GetSnapshots(ctx context.Context, loanID int64, from, to time.Time) ([]loan.Snapshot, error) {
+	snapshots := make([]loan.Snapshot, 0)
+	err := r.scanSnapshots(ctx, "GetSnapshots", func(s loan.Snapshot) error {
+		snapshots = append(snapshots, s)
+		return nil
+	}, `
+        SELECT `+snapshotColumns+`
+        FROM loan_status_snapshots
+        WHERE loan_id = $1 AND snapshot_date BETWEEN $2 AND $3
+        ORDER BY snapshot_date ASC`, loanID, dateArg(from), dateArg(to))
+	if err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
+
+// LatestSnapshotDate, GetPortfolioRows and StreamSnapshots read archived
+// loans' snapshots too, so a past portfolio does not change on archival.
+func (r *LoanRepository) LatestSnapshotDate(ctx context.Context, onOrBefore time.Time) (time.Time, bool, error) {
+	var latest sql.NullString
+	err := r.db.QueryRowContext(ctx, `
+        SELECT MAX(snapshot_date) FROM (
+            SELECT MAX(snapshot_date) AS snapshot_date FROM loan_status_snapshots WHERE snapshot_date <= $1
+            UNION ALL
+            SELECT MAX(snapshot_date) FROM loan_status_snapshots_archive WHERE snapshot_date <= $1
+        ) latest`, dateArg(onOrBefore)).Scan(&latest)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to find latest snapshot date", "error", err)
+		return time.Time{}, false, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	if !latest.Valid {
+		return time.Time{}, false, nil
+	}
+	date, err := parseTimestamp(latest.String)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	return date, true, nil
+}
+
+func (r *LoanRepository) GetPortfolioRows(ctx context.Context, date time.Time) ([]loan.PortfolioRow, error) {
+	start := time.Now()
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT status, dpd, COUNT(*), COALESCE(SUM(outstanding), 0)
+        FROM (
+            SELECT status, dpd, outstanding FROM loan_status_snapshots WHERE snapshot_date = $1
+            UNION ALL
+            SELECT status, dpd, outstanding FROM loan_status_snapshots_archive WHERE snapshot_date = $1
+        ) snapshots
+        GROUP BY status, dpd
+        ORDER BY status, dpd`, dateArg(date))
+	if err != nil {
+		monitoring.RecordDBQuery("GetPortfolioRows", "error", time.Since(start))
+		r.logger.ErrorContext(ctx, "Failed to query portfolio snapshot", "error", err)
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	result := make([]loan.PortfolioRow, 0)
+	for rows.Next() {
+		var row loan.PortfolioRow
+		if err := rows.Scan(&row.Status, &row.DPD, &row.Loans, &row.Outstanding); err != nil {
+			return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+		}
+		result = append(result, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+
+	monitoring.RecordDBQuery("GetPortfolioRows", "success", time.Since(start))
+	return result, nil
+}
+
+func (r *LoanRepository) StreamSnapshots(ctx context.Context, date time.Time, afterLoanID int64, fn func(loan.Snapshot) error) error {
+	return r.scanSnapshots(ctx, "StreamSnapshots", fn, `
+        SELECT `+snapshotColumns+`
+        FROM loan_status_snapshots
+        WHERE snapshot_date = $1 AND loan_id > $2
+        UNION ALL
+        SELECT `+snapshotColumns+`
+        FROM loan_status_snapshots_archive
+        WHERE snapshot_date = $1 AND loan_id > $2
+        ORDER BY loan_id`, dateArg(date), afterLoanID)
+}
+
+// scanSnapshots hands every row of query to fn and records the query under
+// operation. An error from fn is returned unchanged.
+func (r *LoanRepository) scanSnapshots(ctx context.Context, operation string, fn func(loan.Snapshot) error, query string, args ...any) error {
+	start := time.Now()
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		monitoring.RecordDBQuery(operation, "error", time.Since(start))
+		r.logger.ErrorContext(ctx, "Failed to query loan snapshots", "operation", operation, "error", err)
+		return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s loan.Snapshot
+		if err := rows.Scan(&s.LoanID, &s.Date, &s.Status, &s.Outstanding, &s.DPD); err != nil {
+			monitoring.RecordDBQuery(operation, "error", time.Since(start))
+			return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+		}
+		if err := fn(s); err != nil {
+			monitoring.RecordDBQuery(operation, "aborted", time.Since(start))
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		monitoring.RecordDBQuery(operation, "error", time.Since(start))
+		return fmt.Errorf(errMsgFormat, apperrors.ErrDatabase, err)
+	}
+
+	monitoring.RecordDBQuery(operation, "success", time.Since(start))
+	return nil
+}
@@ -0,0 +1,61 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"billing-engine/internal/domain/note"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNoteRepository(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewNoteRepository(db, clock.System(), testLogger)
+	ctx := context.Background()
+	_, created := createTestLoan(t, db, day("2025-01-06"), "")
+	subject := note.Subject{Type: note.SubjectLoan, ID: created.ID}
+
+	exists, err := repo.SubjectExists(ctx, subject)
+	require.NoError(t, err)
+	assert.True(t, exists)
+	exists, err = repo.SubjectExists(ctx, note.Subject{Type: note.SubjectCustomer, ID: 99})
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	first := &note.Note{Subject: subject, Body: "Called the customer"}
+	second := &note.Note{Subject: subject, Body: "Promised to pay", Author: "agent-1"}
+	require.NoError(t, repo.CreateNote(ctx, first))
+	require.NoError(t, repo.CreateNote(ctx, second))
+
+	notes, err := repo.ListNotes(ctx, subject)
+	require.NoError(t, err)
+	require.Len(t, notes, 2)
+	assert.Equal(t, second.ID, notes[0].ID, "newest first")
+	assert.Equal(t, "agent-1", notes[0].Author)
+	assert.Empty(t, notes[1].Author)
+
+	missing := note.Subject{Type: note.SubjectLoan, ID: created.ID + 1}
+	assert.ErrorIs(t, repo.CreateNote(ctx, &note.Note{Subject: missing, Body: "x"}), apperrors.ErrNotFound)
+	assert.ErrorIs(t, repo.DeleteNote(ctx, missing, first.ID), apperrors.ErrNotFound)
+	require.NoError(t, repo.DeleteNote(ctx, subject, first.ID))
+
+	a := &note.Attachment{Subject: subject, FileName: "contract.pdf", ContentType: "application/pdf", SizeBytes: 42, StorageKey: "loan/1/contract.pdf"}
+	require.NoError(t, repo.CreateAttachment(ctx, a))
+	got, err := repo.GetAttachment(ctx, subject, a.ID)
+	require.NoError(t, err)
+	assert.Equal(t, a.StorageKey, got.StorageKey)
+	assert.True(t, a.CreatedAt.Equal(got.CreatedAt))
+
+	attachments, err := repo.ListAttachments(ctx, subject)
+	require.NoError(t, err)
+	assert.Len(t, attachments, 1)
+
+	require.NoError(t, repo.DeleteAttachment(ctx, subject, a.ID))
+	_, err = repo.GetAttachment(ctx, subject, a.ID)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+}
@@ -0,0 +1,14 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"time"
+)
+
+// EnsurePartitions creates nothing: SQLite has no partitioned tables and
+// keeps loan_schedule and payments as plain tables.
+func (r *LoanRepository) EnsurePartitions(ctx context.Context, now time.Time, monthsAhead int) ([]string, error) {
+	return nil, nil
+}
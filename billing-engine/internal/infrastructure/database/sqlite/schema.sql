@@ -0,0 +1,628 @@
+-- Schema of the SQLite development backend, equivalent to the PostgreSQL
+-- migrations 001 to 020. The history tables of 009 are kept by PL/pgSQL
+-- triggers and have no counterpart here, and neither has the trigger change
+-- of 010. Payments made before 012 are not backfilled into the ledger.
+-- SQLite has no partitioned tables, so loan_schedule and payments stay the
+-- plain tables 020 turns into partitions.
+--
+-- Columns declared DATE or TIMESTAMP are returned as time.Time by the driver.
+-- Dates are stored as YYYY-MM-DD and timestamps in UTC, so both order
+-- correctly as text. updated_at is set by the statements themselves since
+-- there is no trigger for it.
+
+CREATE TABLE IF NOT EXISTS loans (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    public_id TEXT NOT NULL UNIQUE,
+    principal_amount REAL NOT NULL CHECK (principal_amount > 0),
+    interest_rate REAL NOT NULL CHECK (interest_rate >= 0),
+    term_weeks INTEGER NOT NULL CHECK (term_weeks > 0),
+    weekly_payment_amount REAL NOT NULL CHECK (weekly_payment_amount >= 0),
+    total_loan_amount REAL NOT NULL CHECK (total_loan_amount >= principal_amount),
+    start_date DATE NOT NULL,
+    status TEXT NOT NULL DEFAULT 'ACTIVE' CHECK (status IN ('ACTIVE', 'PAID_OFF', 'DELINQUENT')),
+    days_past_due INTEGER NOT NULL DEFAULT 0 CHECK (days_past_due >= 0),
+    external_ref TEXT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    next_due_date DATE NULL,
+    next_due_amount REAL NOT NULL DEFAULT 0,
+    installments_paid INTEGER NOT NULL DEFAULT 0,
+    installments_remaining INTEGER NOT NULL DEFAULT 0,
+    schedule_type TEXT NOT NULL DEFAULT 'STANDARD' CHECK (schedule_type IN ('STANDARD', 'INTEREST_ONLY', 'BALLOON')),
+    interest_only_weeks INTEGER NOT NULL DEFAULT 0 CHECK (interest_only_weeks >= 0),
+    balloon_amount REAL NOT NULL DEFAULT 0 CHECK (balloon_amount >= 0),
+    tenant_id TEXT NOT NULL DEFAULT 'default'
+);
+
+CREATE INDEX IF NOT EXISTS idx_loans_days_past_due ON loans (days_past_due) WHERE days_past_due > 0;
+CREATE INDEX IF NOT EXISTS idx_loans_tenant_id ON loans (tenant_id, id);
+
+CREATE TABLE IF NOT EXISTS loan_schedule (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    loan_id INTEGER NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    week_number INTEGER NOT NULL CHECK (week_number > 0),
+    due_date DATE NOT NULL,
+    due_amount REAL NOT NULL CHECK (due_amount >= 0),
+    paid_amount REAL NOT NULL DEFAULT 0 CHECK (paid_amount >= 0),
+    payment_date TIMESTAMP NULL,
+    status TEXT NOT NULL DEFAULT 'PENDING' CHECK (status IN ('PENDING', 'PAID', 'MISSED')),
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    UNIQUE (loan_id, week_number)
+);
+
+CREATE INDEX IF NOT EXISTS idx_loan_schedule_loan_id_status ON loan_schedule (loan_id, status);
+CREATE INDEX IF NOT EXISTS idx_loan_schedule_due_date ON loan_schedule (due_date);
+
+-- The loan's next installment and installment counts, recomputed with every
+-- write to its schedule as migration 033's trigger does.
+CREATE TRIGGER IF NOT EXISTS refresh_loan_installments_insert
+AFTER INSERT ON loan_schedule
+BEGIN
+    UPDATE loans SET
+        next_due_date = (SELECT due_date FROM loan_schedule WHERE loan_id = NEW.loan_id AND status != 'PAID' ORDER BY due_date, week_number LIMIT 1),
+        next_due_amount = COALESCE((SELECT ROUND(due_amount - paid_amount, 2) FROM loan_schedule WHERE loan_id = NEW.loan_id AND status != 'PAID' ORDER BY due_date, week_number LIMIT 1), 0),
+        installments_paid = (SELECT COUNT(*) FROM loan_schedule WHERE loan_id = NEW.loan_id AND status = 'PAID'),
+        installments_remaining = (SELECT COUNT(*) FROM loan_schedule WHERE loan_id = NEW.loan_id AND status != 'PAID')
+    WHERE id = NEW.loan_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS refresh_loan_installments_update
+AFTER UPDATE ON loan_schedule
+BEGIN
+    UPDATE loans SET
+        next_due_date = (SELECT due_date FROM loan_schedule WHERE loan_id = NEW.loan_id AND status != 'PAID' ORDER BY due_date, week_number LIMIT 1),
+        next_due_amount = COALESCE((SELECT ROUND(due_amount - paid_amount, 2) FROM loan_schedule WHERE loan_id = NEW.loan_id AND status != 'PAID' ORDER BY due_date, week_number LIMIT 1), 0),
+        installments_paid = (SELECT COUNT(*) FROM loan_schedule WHERE loan_id = NEW.loan_id AND status = 'PAID'),
+        installments_remaining = (SELECT COUNT(*) FROM loan_schedule WHERE loan_id = NEW.loan_id AND status != 'PAID')
+    WHERE id = NEW.loan_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS refresh_loan_installments_delete
+AFTER DELETE ON loan_schedule
+BEGIN
+    UPDATE loans SET
+        next_due_date = (SELECT due_date FROM loan_schedule WHERE loan_id = OLD.loan_id AND status != 'PAID' ORDER BY due_date, week_number LIMIT 1),
+        next_due_amount = COALESCE((SELECT ROUND(due_amount - paid_amount, 2) FROM loan_schedule WHERE loan_id = OLD.loan_id AND status != 'PAID' ORDER BY due_date, week_number LIMIT 1), 0),
+        installments_paid = (SELECT COUNT(*) FROM loan_schedule WHERE loan_id = OLD.loan_id AND status = 'PAID'),
+        installments_remaining = (SELECT COUNT(*) FROM loan_schedule WHERE loan_id = OLD.loan_id AND status != 'PAID')
+    WHERE id = OLD.loan_id;
+END;
+
+CREATE TABLE IF NOT EXISTS customers (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    public_id TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL,
+    address TEXT NOT NULL,
+    is_delinquent BOOLEAN NOT NULL DEFAULT FALSE,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    loan_id INTEGER NULL UNIQUE REFERENCES loans(id) ON DELETE SET NULL,
+    external_ref TEXT NULL UNIQUE,
+    risk_score INTEGER NULL CHECK (risk_score BETWEEN 0 AND 1000),
+    risk_grade TEXT NULL,
+    risk_scored_at TIMESTAMP NULL,
+    merged_into_id INTEGER NULL REFERENCES customers(id),
+    merged_at TIMESTAMP NULL,
+    notified_for_dpd_bucket TEXT NULL,
+    last_notified_delinquency_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    tenant_id TEXT NOT NULL DEFAULT 'default',
+    CHECK ((risk_score IS NULL) = (risk_grade IS NULL) AND (risk_grade IS NULL) = (risk_scored_at IS NULL)),
+    CHECK ((merged_into_id IS NULL) = (merged_at IS NULL) AND merged_into_id IS NOT id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_customers_active ON customers (active);
+CREATE INDEX IF NOT EXISTS idx_customers_merged_into_id ON customers (merged_into_id) WHERE merged_into_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_customers_name ON customers (name);
+CREATE INDEX IF NOT EXISTS idx_customers_tenant_id ON customers (tenant_id, id);
+
+CREATE TABLE IF NOT EXISTS notes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    loan_id INTEGER NULL REFERENCES loans(id) ON DELETE CASCADE,
+    customer_id INTEGER NULL REFERENCES customers(id) ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    author TEXT NULL,
+    created_at TIMESTAMP NOT NULL,
+    CHECK ((loan_id IS NULL) <> (customer_id IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_notes_loan_id ON notes (loan_id) WHERE loan_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_notes_customer_id ON notes (customer_id) WHERE customer_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS attachments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    loan_id INTEGER NULL REFERENCES loans(id) ON DELETE CASCADE,
+    customer_id INTEGER NULL REFERENCES customers(id) ON DELETE CASCADE,
+    file_name TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size_bytes INTEGER NOT NULL CHECK (size_bytes >= 0),
+    storage_key TEXT NOT NULL UNIQUE,
+    uploaded_by TEXT NULL,
+    created_at TIMESTAMP NOT NULL,
+    CHECK ((loan_id IS NULL) <> (customer_id IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachments_loan_id ON attachments (loan_id) WHERE loan_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_attachments_customer_id ON attachments (customer_id) WHERE customer_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS loan_status_snapshots (
+    snapshot_date DATE NOT NULL,
+    loan_id INTEGER NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    status TEXT NOT NULL CHECK (status IN ('ACTIVE', 'PAID_OFF', 'DELINQUENT')),
+    outstanding REAL NOT NULL CHECK (outstanding >= 0),
+    dpd INTEGER NOT NULL DEFAULT 0 CHECK (dpd >= 0),
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (loan_id, snapshot_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_loan_status_snapshots_date ON loan_status_snapshots (snapshot_date);
+
+CREATE TABLE IF NOT EXISTS mandates (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    customer_id INTEGER NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    reference TEXT NOT NULL UNIQUE,
+    account_holder TEXT NOT NULL,
+    account_number TEXT NOT NULL,
+    bank_code TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'ACTIVE' CHECK (status IN ('ACTIVE', 'CANCELLED')),
+    signed_at DATE NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_mandates_active_customer ON mandates (customer_id) WHERE status = 'ACTIVE';
+
+CREATE TABLE IF NOT EXISTS direct_debit_instructions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    mandate_id INTEGER NOT NULL REFERENCES mandates(id) ON DELETE CASCADE,
+    loan_id INTEGER NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    schedule_id INTEGER NOT NULL REFERENCES loan_schedule(id) ON DELETE CASCADE,
+    week_number INTEGER NOT NULL CHECK (week_number > 0),
+    end_to_end_id TEXT NOT NULL UNIQUE,
+    amount REAL NOT NULL CHECK (amount > 0),
+    collection_date DATE NOT NULL,
+    status TEXT NOT NULL DEFAULT 'PENDING' CHECK (status IN ('PENDING', 'EXPORTED', 'COLLECTED', 'FAILED', 'UNAPPLIED')),
+    batch_ref TEXT NULL,
+    failure_reason TEXT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_direct_debit_instructions_open_schedule
+    ON direct_debit_instructions (schedule_id) WHERE status <> 'FAILED';
+CREATE INDEX IF NOT EXISTS idx_direct_debit_instructions_status
+    ON direct_debit_instructions (status, collection_date);
+
+CREATE TABLE IF NOT EXISTS payments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    loan_id INTEGER NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    schedule_id INTEGER NULL REFERENCES loan_schedule(id) ON DELETE CASCADE,
+    amount REAL NOT NULL CHECK (amount > 0),
+    channel TEXT NOT NULL DEFAULT 'UNSPECIFIED' CHECK (channel IN ('CASH', 'BANK_TRANSFER', 'GATEWAY', 'DIRECT_DEBIT', 'UNSPECIFIED')),
+    reference TEXT NULL,
+    collector_id TEXT NULL,
+    paid_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    fee_id INTEGER NULL REFERENCES fees(id) ON DELETE CASCADE,
+    UNIQUE (channel, reference),
+    CHECK ((schedule_id IS NULL) <> (fee_id IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_payments_paid_at ON payments (paid_at);
+CREATE INDEX IF NOT EXISTS idx_payments_loan_id ON payments (loan_id);
+CREATE INDEX IF NOT EXISTS idx_payments_reference ON payments (reference) WHERE reference IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS collection_rules (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    collector TEXT NOT NULL,
+    bucket TEXT NULL CHECK (bucket IN ('1-30', '31-60', '61-90', '90+')),
+    region TEXT NULL,
+    min_outstanding REAL NULL CHECK (min_outstanding >= 0),
+    max_outstanding REAL NULL,
+    priority INTEGER NOT NULL DEFAULT 0,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    CHECK (max_outstanding >= min_outstanding)
+);
+
+CREATE TABLE IF NOT EXISTS collection_assignments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    loan_id INTEGER NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    customer_id INTEGER NULL REFERENCES customers(id) ON DELETE SET NULL,
+    collector TEXT NOT NULL,
+    rule_id INTEGER NULL REFERENCES collection_rules(id) ON DELETE SET NULL,
+    status TEXT NOT NULL DEFAULT 'OPEN' CHECK (status IN ('OPEN', 'RESOLVED')),
+    resolution TEXT NULL CHECK (resolution IN ('CURED', 'PAID_OFF')),
+    assigned_at TIMESTAMP NOT NULL,
+    resolved_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    CHECK ((status = 'RESOLVED') = (resolved_at IS NOT NULL))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_collection_assignments_open_loan ON collection_assignments (loan_id) WHERE status = 'OPEN';
+CREATE INDEX IF NOT EXISTS idx_collection_assignments_open_collector ON collection_assignments (collector) WHERE status = 'OPEN';
+
+CREATE TABLE IF NOT EXISTS collection_actions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    assignment_id INTEGER NOT NULL REFERENCES collection_assignments(id) ON DELETE CASCADE,
+    loan_id INTEGER NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    collector TEXT NULL,
+    type TEXT NOT NULL CHECK (type IN ('CALL', 'SMS', 'VISIT', 'PROMISE_TO_PAY', 'NOTE', 'REASSIGNED')),
+    note TEXT NULL,
+    promised_amount REAL NULL CHECK (promised_amount > 0),
+    promised_date DATE NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_collection_actions_assignment_id ON collection_actions (assignment_id);
+
+CREATE TABLE IF NOT EXISTS escalation_steps (
+    days_past_due INTEGER PRIMARY KEY CHECK (days_past_due BETWEEN 1 AND 3650),
+    action TEXT NOT NULL CHECK (action IN ('SMS', 'EMAIL', 'CALL', 'LETTER')),
+    created_at TIMESTAMP NOT NULL
+);
+
+INSERT OR IGNORE INTO escalation_steps (days_past_due, action, created_at) VALUES
+    (1, 'SMS', CURRENT_TIMESTAMP),
+    (7, 'EMAIL', CURRENT_TIMESTAMP),
+    (14, 'CALL', CURRENT_TIMESTAMP),
+    (30, 'LETTER', CURRENT_TIMESTAMP);
+
+CREATE TABLE IF NOT EXISTS loan_escalations (
+    loan_id INTEGER PRIMARY KEY REFERENCES loans(id) ON DELETE CASCADE,
+    days_past_due INTEGER NOT NULL,
+    escalated_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS event_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id TEXT NOT NULL UNIQUE,
+    event_type TEXT NOT NULL,
+    entity_id INTEGER NOT NULL,
+    sequence INTEGER NULL,
+    payload TEXT NOT NULL,
+    occurred_at TIMESTAMP NOT NULL,
+    published_at TIMESTAMP NULL,
+    replay_count INTEGER NOT NULL DEFAULT 0,
+    last_replayed_at TIMESTAMP NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_log_entity_id ON event_log (entity_id);
+CREATE INDEX IF NOT EXISTS idx_event_log_occurred_at ON event_log (occurred_at);
+
+CREATE TABLE IF NOT EXISTS event_sequences (
+    entity_id INTEGER PRIMARY KEY,
+    last_sequence INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS customer_loan_summary (
+    customer_id INTEGER PRIMARY KEY REFERENCES customers(id) ON DELETE CASCADE,
+    customer_public_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    address TEXT NOT NULL,
+    active BOOLEAN NOT NULL,
+    is_delinquent BOOLEAN NOT NULL,
+    loan_id INTEGER NULL,
+    loan_public_id TEXT NULL,
+    loan_status TEXT NULL,
+    principal_amount REAL NOT NULL DEFAULT 0,
+    total_loan_amount REAL NOT NULL DEFAULT 0,
+    total_paid REAL NOT NULL DEFAULT 0,
+    outstanding REAL NOT NULL DEFAULT 0,
+    installments_paid INTEGER NOT NULL DEFAULT 0,
+    installments_total INTEGER NOT NULL DEFAULT 0,
+    next_due_date DATE NULL,
+    next_due_amount REAL NOT NULL DEFAULT 0,
+    days_past_due INTEGER NOT NULL DEFAULT 0,
+    refreshed_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_customer_loan_summary_loan_id ON customer_loan_summary (loan_id) WHERE loan_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS loan_holds (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    loan_id INTEGER NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL CHECK (reason <> ''),
+    placed_by TEXT NULL,
+    placed_at TIMESTAMP NOT NULL,
+    released_by TEXT NULL,
+    released_at TIMESTAMP NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_loan_holds_open_loan ON loan_holds (loan_id) WHERE released_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS integrity_findings (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    check_name TEXT NOT NULL,
+    entity_type TEXT NOT NULL,
+    entity_id INTEGER NOT NULL,
+    detail TEXT NOT NULL,
+    first_seen_at TIMESTAMP NOT NULL,
+    last_seen_at TIMESTAMP NOT NULL,
+    UNIQUE (check_name, entity_id)
+);
+
+-- See migrations/021_create_jobs.sql. A single instance uses a SQLite file,
+-- so claims need no row locks.
+CREATE TABLE IF NOT EXISTS jobs (
+    id TEXT PRIMARY KEY,
+    kind TEXT NOT NULL,
+    status TEXT NOT NULL,
+    dedupe_key TEXT NULL UNIQUE,
+    payload TEXT NULL,
+    result TEXT NULL,
+    error TEXT NULL,
+    total INTEGER NOT NULL DEFAULT 0,
+    processed INTEGER NOT NULL DEFAULT 0,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    worker TEXT NULL,
+    created_at TIMESTAMP NOT NULL,
+    started_at TIMESTAMP NULL,
+    heartbeat_at TIMESTAMP NULL,
+    finished_at TIMESTAMP NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_jobs_status_created_at ON jobs (status, created_at);
+
+-- See migrations/031_create_job_runs.sql.
+CREATE TABLE IF NOT EXISTS job_runs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    job_id TEXT NULL,
+    status TEXT NOT NULL CHECK (status IN ('RUNNING', 'SUCCEEDED', 'FAILED')),
+    total INTEGER NOT NULL DEFAULT 0,
+    processed INTEGER NOT NULL DEFAULT 0,
+    failed INTEGER NOT NULL DEFAULT 0,
+    error_samples TEXT NOT NULL DEFAULT '[]',
+    error TEXT NULL,
+    started_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    finished_at TIMESTAMP NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_job_runs_name_started_at ON job_runs (name, started_at);
+
+-- See migrations/032_create_job_locks.sql.
+CREATE TABLE IF NOT EXISTS job_locks (
+    name TEXT PRIMARY KEY,
+    owner TEXT NOT NULL,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS customer_preferences (
+    customer_id INTEGER PRIMARY KEY REFERENCES customers(id) ON DELETE CASCADE,
+    preferred_channel TEXT NOT NULL DEFAULT '' CHECK (preferred_channel IN ('', 'sms', 'email')),
+    language TEXT NOT NULL DEFAULT '',
+    marketing_opt_out BOOLEAN NOT NULL DEFAULT FALSE,
+    transactional_opt_out BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at TIMESTAMP NOT NULL
+);
+
+-- The archive mirrors the columns of the live tables, see
+-- migrations/019_create_loan_archive.sql. A column added to a live table has
+-- to be added to its archive table as well.
+CREATE TABLE IF NOT EXISTS rate_history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    loan_id INTEGER NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    previous_rate REAL NOT NULL CHECK (previous_rate >= 0),
+    rate REAL NOT NULL CHECK (rate >= 0),
+    effective_from DATE NOT NULL,
+    changed_by TEXT NULL,
+    created_at TIMESTAMP NOT NULL,
+    UNIQUE (loan_id, effective_from)
+);
+
+CREATE TABLE IF NOT EXISTS schedule_adjustments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    loan_id INTEGER NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('PAYMENT_HOLIDAY', 'ZERO_INTEREST')),
+    starts_on DATE NOT NULL,
+    weeks INTEGER NOT NULL CHECK (weeks > 0),
+    reason TEXT NOT NULL,
+    applied_by TEXT NULL,
+    applied_at TIMESTAMP NOT NULL,
+    removed_by TEXT NULL,
+    removed_at TIMESTAMP NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_schedule_adjustments_loan_id ON schedule_adjustments (loan_id);
+
+CREATE TABLE IF NOT EXISTS prepayments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    loan_id INTEGER NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    option TEXT NOT NULL CHECK (option IN ('REDUCE_TERM', 'REDUCE_INSTALLMENT')),
+    amount REAL NOT NULL CHECK (amount > 0),
+    after_week INTEGER NOT NULL CHECK (after_week >= 0),
+    principal REAL NOT NULL CHECK (principal > 0),
+    weekly_principal REAL NOT NULL CHECK (weekly_principal > 0),
+    previous_term_weeks INTEGER NOT NULL,
+    previous_weekly_payment_amount REAL NOT NULL,
+    previous_total_loan_amount REAL NOT NULL,
+    term_weeks INTEGER NOT NULL CHECK (term_weeks > after_week),
+    weekly_payment_amount REAL NOT NULL,
+    total_loan_amount REAL NOT NULL,
+    channel TEXT NOT NULL DEFAULT 'UNSPECIFIED' CHECK (channel IN ('CASH', 'BANK_TRANSFER', 'GATEWAY', 'DIRECT_DEBIT', 'UNSPECIFIED')),
+    reference TEXT NULL,
+    recorded_by TEXT NULL,
+    paid_at TIMESTAMP NOT NULL,
+    UNIQUE (channel, reference)
+);
+
+CREATE INDEX IF NOT EXISTS idx_prepayments_loan_id ON prepayments (loan_id);
+CREATE INDEX IF NOT EXISTS idx_prepayments_paid_at ON prepayments (paid_at);
+CREATE INDEX IF NOT EXISTS idx_prepayments_reference ON prepayments (reference) WHERE reference IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS fees (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    loan_id INTEGER NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    fee_type TEXT NOT NULL CHECK (fee_type IN ('PROCESSING', 'BOUNCE', 'LEGAL', 'LATE')),
+    amount REAL NOT NULL CHECK (amount > 0),
+    reason TEXT NOT NULL CHECK (reason <> ''),
+    posted_by TEXT NULL,
+    posted_at TIMESTAMP NOT NULL,
+    paid_at TIMESTAMP NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_fees_loan_id ON fees (loan_id);
+
+CREATE TABLE IF NOT EXISTS fee_waivers (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    fee_id INTEGER NOT NULL REFERENCES fees(id) ON DELETE CASCADE,
+    loan_id INTEGER NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL CHECK (reason <> ''),
+    status TEXT NOT NULL CHECK (status IN ('PENDING', 'APPROVED', 'REJECTED')),
+    requested_by TEXT NULL,
+    requested_at TIMESTAMP NOT NULL,
+    decided_by TEXT NULL,
+    decided_at TIMESTAMP NULL,
+    CHECK ((status = 'PENDING') = (decided_at IS NULL))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_fee_waivers_open_fee ON fee_waivers (fee_id) WHERE status <> 'REJECTED';
+CREATE INDEX IF NOT EXISTS idx_fee_waivers_loan_id ON fee_waivers (loan_id);
+
+CREATE TABLE IF NOT EXISTS tax_lines (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    fee_id INTEGER NOT NULL UNIQUE REFERENCES fees(id) ON DELETE CASCADE,
+    loan_id INTEGER NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    jurisdiction TEXT NOT NULL CHECK (jurisdiction <> ''),
+    rate REAL NOT NULL CHECK (rate > 0 AND rate < 1),
+    taxable_amount REAL NOT NULL CHECK (taxable_amount > 0),
+    amount REAL NOT NULL CHECK (amount > 0),
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_tax_lines_loan_id ON tax_lines (loan_id);
+CREATE INDEX IF NOT EXISTS idx_tax_lines_created_at ON tax_lines (created_at);
+
+CREATE TABLE IF NOT EXISTS documents (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    loan_id INTEGER NULL REFERENCES loans(id) ON DELETE CASCADE,
+    type TEXT NOT NULL CHECK (type IN ('CONTRACT', 'ID_COPY', 'PAYOFF_CERTIFICATE')),
+    file_name TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size_bytes INTEGER NOT NULL CHECK (size_bytes > 0),
+    storage_key TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL,
+    customer_id INTEGER NULL REFERENCES customers(id) ON DELETE CASCADE,
+    status TEXT NOT NULL DEFAULT 'AVAILABLE' CHECK (status IN ('PENDING', 'AVAILABLE')),
+    uploaded_by TEXT NULL,
+    CHECK ((loan_id IS NULL) <> (customer_id IS NULL))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_documents_payoff_certificate ON documents (loan_id) WHERE type = 'PAYOFF_CERTIFICATE';
+CREATE INDEX IF NOT EXISTS idx_documents_loan_id ON documents (loan_id) WHERE loan_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_documents_customer_id ON documents (customer_id) WHERE customer_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_documents_type_created_at ON documents (type, created_at);
+
+CREATE TABLE IF NOT EXISTS loan_agreements (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    loan_id INTEGER NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    document_id INTEGER NULL REFERENCES documents(id) ON DELETE SET NULL,
+    hold_id INTEGER NULL REFERENCES loan_holds(id) ON DELETE SET NULL,
+    provider TEXT NOT NULL,
+    envelope_id TEXT NOT NULL,
+    status TEXT NOT NULL CHECK (status IN ('SENT', 'COMPLETED', 'DECLINED', 'VOIDED')),
+    sent_by TEXT NULL,
+    sent_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    signed_at TIMESTAMP NULL,
+    disbursed_by TEXT NULL,
+    disbursed_at TIMESTAMP NULL,
+    UNIQUE (provider, envelope_id),
+    CHECK (disbursed_at IS NULL OR status = 'COMPLETED')
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_loan_agreements_open ON loan_agreements (loan_id) WHERE status IN ('SENT', 'COMPLETED');
+CREATE INDEX IF NOT EXISTS idx_loan_agreements_loan_id ON loan_agreements (loan_id, sent_at);
+
+CREATE TABLE IF NOT EXISTS archived_loans (
+    loan_id INTEGER PRIMARY KEY,
+    public_id TEXT NOT NULL,
+    customer_id INTEGER NULL,
+    completed_at TIMESTAMP NOT NULL,
+    archived_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS loans_archive AS SELECT * FROM loans WHERE 0;
+CREATE TABLE IF NOT EXISTS loan_schedule_archive AS SELECT * FROM loan_schedule WHERE 0;
+CREATE TABLE IF NOT EXISTS payments_archive AS SELECT * FROM payments WHERE 0;
+CREATE TABLE IF NOT EXISTS direct_debit_instructions_archive AS SELECT * FROM direct_debit_instructions WHERE 0;
+CREATE TABLE IF NOT EXISTS loan_status_snapshots_archive AS SELECT * FROM loan_status_snapshots WHERE 0;
+CREATE TABLE IF NOT EXISTS collection_assignments_archive AS SELECT * FROM collection_assignments WHERE 0;
+CREATE TABLE IF NOT EXISTS collection_actions_archive AS SELECT * FROM collection_actions WHERE 0;
+CREATE TABLE IF NOT EXISTS loan_holds_archive AS SELECT * FROM loan_holds WHERE 0;
+CREATE TABLE IF NOT EXISTS notes_archive AS SELECT * FROM notes WHERE 0;
+CREATE TABLE IF NOT EXISTS attachments_archive AS SELECT * FROM attachments WHERE 0;
+CREATE TABLE IF NOT EXISTS rate_history_archive AS SELECT * FROM rate_history WHERE 0;
+CREATE TABLE IF NOT EXISTS schedule_adjustments_archive AS SELECT * FROM schedule_adjustments WHERE 0;
+CREATE TABLE IF NOT EXISTS prepayments_archive AS SELECT * FROM prepayments WHERE 0;
+CREATE TABLE IF NOT EXISTS fees_archive AS SELECT * FROM fees WHERE 0;
+CREATE TABLE IF NOT EXISTS fee_waivers_archive AS SELECT * FROM fee_waivers WHERE 0;
+CREATE TABLE IF NOT EXISTS tax_lines_archive AS SELECT * FROM tax_lines WHERE 0;
+CREATE TABLE IF NOT EXISTS documents_archive AS SELECT * FROM documents WHERE 0;
+CREATE TABLE IF NOT EXISTS loan_agreements_archive AS SELECT * FROM loan_agreements WHERE 0;
+
+CREATE INDEX IF NOT EXISTS idx_loans_archive_id ON loans_archive (id);
+CREATE INDEX IF NOT EXISTS idx_loan_schedule_archive_loan_id ON loan_schedule_archive (loan_id);
+CREATE INDEX IF NOT EXISTS idx_payments_archive_loan_id ON payments_archive (loan_id);
+CREATE INDEX IF NOT EXISTS idx_direct_debit_instructions_archive_loan_id ON direct_debit_instructions_archive (loan_id);
+CREATE INDEX IF NOT EXISTS idx_loan_status_snapshots_archive_loan_id ON loan_status_snapshots_archive (loan_id);
+CREATE INDEX IF NOT EXISTS idx_collection_assignments_archive_loan_id ON collection_assignments_archive (loan_id);
+CREATE INDEX IF NOT EXISTS idx_collection_actions_archive_loan_id ON collection_actions_archive (loan_id);
+CREATE INDEX IF NOT EXISTS idx_loan_holds_archive_loan_id ON loan_holds_archive (loan_id);
+CREATE INDEX IF NOT EXISTS idx_notes_archive_loan_id ON notes_archive (loan_id);
+CREATE INDEX IF NOT EXISTS idx_attachments_archive_loan_id ON attachments_archive (loan_id);
+CREATE INDEX IF NOT EXISTS idx_rate_history_archive_loan_id ON rate_history_archive (loan_id);
+CREATE INDEX IF NOT EXISTS idx_schedule_adjustments_archive_loan_id ON schedule_adjustments_archive (loan_id);
+CREATE INDEX IF NOT EXISTS idx_prepayments_archive_loan_id ON prepayments_archive (loan_id);
+CREATE INDEX IF NOT EXISTS idx_fees_archive_loan_id ON fees_archive (loan_id);
+CREATE INDEX IF NOT EXISTS idx_fee_waivers_archive_loan_id ON fee_waivers_archive (loan_id);
+CREATE INDEX IF NOT EXISTS idx_tax_lines_archive_loan_id ON tax_lines_archive (loan_id);
+CREATE INDEX IF NOT EXISTS idx_documents_archive_loan_id ON documents_archive (loan_id);
+CREATE INDEX IF NOT EXISTS idx_loan_agreements_archive_loan_id ON loan_agreements_archive (loan_id);
+CREATE INDEX IF NOT EXISTS idx_payments_archive_paid_at ON payments_archive (paid_at);
+CREATE INDEX IF NOT EXISTS idx_prepayments_archive_paid_at ON prepayments_archive (paid_at);
+CREATE INDEX IF NOT EXISTS idx_tax_lines_archive_created_at ON tax_lines_archive (created_at);
+CREATE INDEX IF NOT EXISTS idx_loan_status_snapshots_archive_date ON loan_status_snapshots_archive (snapshot_date, loan_id);
+
+CREATE TABLE IF NOT EXISTS customer_contacts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    customer_id INTEGER NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    type TEXT NOT NULL CHECK (type IN ('phone', 'email')),
+    value TEXT NOT NULL,
+    verified BOOLEAN NOT NULL DEFAULT FALSE,
+    is_primary BOOLEAN NOT NULL DEFAULT FALSE CHECK (NOT is_primary OR verified),
+    verified_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    UNIQUE (customer_id, type, value)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_customer_contacts_primary ON customer_contacts (customer_id, type) WHERE is_primary;
+
+CREATE TABLE IF NOT EXISTS customer_contact_verifications (
+    contact_id INTEGER PRIMARY KEY REFERENCES customer_contacts(id) ON DELETE CASCADE,
+    value TEXT NOT NULL,
+    code_hash TEXT NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    sent_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS tenant_settings (
+    tenant_id TEXT PRIMARY KEY,
+    missed_payments INTEGER NULL CHECK (missed_payments >= 1),
+    cure_payments INTEGER NULL CHECK (cure_payments >= 1),
+    rate_limit_rps REAL NULL CHECK (rate_limit_rps > 0),
+    rate_limit_burst INTEGER NULL CHECK (rate_limit_burst >= 1),
+    grace_days INTEGER NULL CHECK (grace_days >= 0),
+    late_fee REAL NULL CHECK (late_fee >= 0),
+    reminder_window_days INTEGER NULL CHECK (reminder_window_days >= 0),
+    updated_by TEXT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1,107 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"billing-engine/internal/domain/summary"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+)
+
+const summaryColumns = `customer_id, customer_public_id, name, address, active, is_delinquent, loan_id, loan_public_id, loan_status,
+        principal_amount, total_loan_amount, total_paid, outstanding, installments_paid, installments_total,
+        next_due_date, next_due_amount, days_past_due, refreshed_at`
+
+// computeSummariesQuery is the SQLite form of the PostgreSQL statement, with
+// correlated subqueries in place of the lateral joins. SQLite needs a WHERE
+// clause between the SELECT and ON CONFLICT, so the rebuild appends one that
+// matches every customer.
+const computeSummariesQuery = `
+        INSERT INTO customer_loan_summary (` + summaryColumns + `)
+        SELECT c.id, c.public_id, c.name, c.address, c.active, c.is_delinquent, l.id, l.public_id, l.status,
+               COALESCE(l.principal_amount, 0), COALESCE(l.total_loan_amount, 0),
+               COALESCE((SELECT SUM(s.paid_amount) FROM loan_schedule s WHERE s.loan_id = l.id), 0),
+               ` + loanOutstanding + `,
+               (SELECT COUNT(*) FROM loan_schedule s WHERE s.loan_id = l.id AND s.status = 'PAID'),
+               (SELECT COUNT(*) FROM loan_schedule s WHERE s.loan_id = l.id),
+               (SELECT s.due_date FROM loan_schedule s WHERE s.loan_id = l.id AND s.status != 'PAID'
+                ORDER BY s.due_date, s.week_number LIMIT 1),
+               COALESCE((SELECT s.due_amount - s.paid_amount FROM loan_schedule s WHERE s.loan_id = l.id AND s.status != 'PAID'
+                ORDER BY s.due_date, s.week_number LIMIT 1), 0),
+               COALESCE(l.days_past_due, 0), $1
+        FROM customers c
+        LEFT JOIN loans l ON l.id = c.loan_id`
+
+const upsertSummaryClause = `
+        ON CONFLICT (customer_id) DO UPDATE SET
+            customer_public_id = excluded.customer_public_id, name = excluded.name, address = excluded.address,
+            active = excluded.active, is_delinquent = excluded.is_delinquent,
+            loan_id = excluded.loan_id, loan_public_id = excluded.loan_public_id, loan_status = excluded.loan_status,
+            principal_amount = excluded.principal_amount, total_loan_amount = excluded.total_loan_amount,
+            total_paid = excluded.total_paid, outstanding = excluded.outstanding,
+            installments_paid = excluded.installments_paid, installments_total = excluded.installments_total,
+            next_due_date = excluded.next_due_date, next_due_amount = excluded.next_due_amount,
+            days_past_due = excluded.days_past_due, refreshed_at = excluded.refreshed_at`
+
+type SummaryRepository struct {
+	db     *sql.DB
+	clock  clock.Clock
+	logger *slog.Logger
+}
+
+var _ summary.Repository = (*SummaryRepository)(nil)
+
+func NewSummaryRepository(db *sql.DB, clk clock.Clock, logger *slog.Logger) *SummaryRepository {
+	return &SummaryRepository{db: db, clock: clock.OrSystem(clk), logger: logger.With("component", "SummaryRepository")}
+}
+
+func (r *SummaryRepository) Get(ctx context.Context, customerID int64) (*summary.CustomerLoanSummary, error) {
+	var s summary.CustomerLoanSummary
+	err := r.db.QueryRowContext(ctx, `SELECT `+summaryColumns+` FROM customer_loan_summary WHERE customer_id = $1`, customerID).Scan(
+		&s.CustomerID, &s.CustomerPublicID, &s.Name, &s.Address, &s.Active, &s.IsDelinquent,
+		&s.LoanID, &s.LoanPublicID, &s.LoanStatus, &s.PrincipalAmount, &s.TotalLoanAmount, &s.TotalPaid, &s.Outstanding,
+		&s.InstallmentsPaid, &s.InstallmentsTotal, &s.NextDueDate, &s.NextDueAmount, &s.DaysPastDue, &s.RefreshedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: no summary for customer %d", apperrors.ErrNotFound, customerID)
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to read customer summary", slog.Int64("customerID", customerID), slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to read customer summary: %w", apperrors.ErrDatabase, err)
+	}
+	return &s, nil
+}
+
+func (r *SummaryRepository) Refresh(ctx context.Context, customerID int64) error {
+	n, err := r.refresh(ctx, `WHERE c.id = $2`, customerID)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: customer %d", apperrors.ErrNotFound, customerID)
+	}
+	return nil
+}
+
+func (r *SummaryRepository) RefreshForLoan(ctx context.Context, loanID int64) error {
+	_, err := r.refresh(ctx, `WHERE c.loan_id = $2`, loanID)
+	return err
+}
+
+func (r *SummaryRepository) Rebuild(ctx context.Context) (int64, error) {
+	return r.refresh(ctx, `WHERE true`)
+}
+
+func (r *SummaryRepository) refresh(ctx context.Context, where string, args ...any) (int64, error) {
+	res, err := r.db.ExecContext(ctx, computeSummariesQuery+"\n        "+where+upsertSummaryClause, append([]any{now(r.clock)}, args...)...)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to refresh customer summaries", slog.Any("error", err))
+		return 0, fmt.Errorf("%w: failed to refresh customer summaries: %w", apperrors.ErrDatabase, err)
+	}
+	return res.RowsAffected()
+}
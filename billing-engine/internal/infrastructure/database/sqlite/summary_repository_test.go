@@ -0,0 +1,70 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummaryRepository(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewSummaryRepository(db, clock.System(), testLogger)
+	loans := NewLoanRepository(db, clock.System(), testLogger)
+	ctx := context.Background()
+	customerID, created := createTestLoan(t, db, day("2025-01-06"), "")
+
+	_, err := repo.Get(ctx, customerID)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound, "nothing is projected before a refresh")
+	assert.ErrorIs(t, repo.Refresh(ctx, customerID+100), apperrors.ErrNotFound)
+
+	require.NoError(t, repo.Refresh(ctx, customerID))
+	s, err := repo.Get(ctx, customerID)
+	require.NoError(t, err)
+	assert.Equal(t, "Jane Doe", s.Name)
+	require.NotNil(t, s.LoanID)
+	assert.Equal(t, created.ID, *s.LoanID)
+	assert.Equal(t, loan.StatusActive, *s.LoanStatus)
+	assert.Equal(t, 330.0, s.Outstanding)
+	assert.Equal(t, 3, s.InstallmentsTotal)
+	require.NotNil(t, s.NextDueDate)
+	assert.Equal(t, day("2025-01-13"), s.NextDueDate.UTC())
+
+	require.NoError(t, loans.WithinTransaction(ctx, func(tx loan.TxRepository) error {
+		entry, err := tx.FindOldestUnpaidEntryForUpdate(ctx, created.ID)
+		require.NoError(t, err)
+		paidAt := time.Now()
+		entry.Status = loan.PaymentStatusPaid
+		entry.PaidAmount = entry.DueAmount
+		entry.PaymentDate = &paidAt
+		return tx.UpdateScheduleEntry(ctx, entry)
+	}))
+	require.NoError(t, loans.UpdateDaysPastDue(ctx, created.ID, 4))
+
+	require.NoError(t, repo.RefreshForLoan(ctx, created.ID))
+	s, err = repo.Get(ctx, customerID)
+	require.NoError(t, err)
+	assert.Equal(t, 110.0, s.TotalPaid)
+	assert.Equal(t, 220.0, s.Outstanding)
+	assert.Equal(t, 1, s.InstallmentsPaid)
+	assert.Equal(t, day("2025-01-20"), s.NextDueDate.UTC())
+	assert.Equal(t, 4, s.DaysPastDue)
+
+	other := customer.NewCustomer("John Roe", "2 Main St")
+	require.NoError(t, NewCustomerRepository(db, clock.System(), testLogger).Save(ctx, other))
+	rows, err := repo.Rebuild(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), rows)
+	s, err = repo.Get(ctx, other.CustomerID)
+	require.NoError(t, err)
+	assert.Nil(t, s.LoanID, "a customer without a loan has no loan figures")
+	assert.Zero(t, s.Outstanding)
+}
@@ -0,0 +1,71 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"billing-engine/internal/domain/tenant"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+type TenantSettingsRepository struct {
+	db     *sql.DB
+	clock  clock.Clock
+	logger *slog.Logger
+}
+
+var _ tenant.Repository = (*TenantSettingsRepository)(nil)
+
+func NewTenantSettingsRepository(db *sql.DB, clk clock.Clock, logger *slog.Logger) *TenantSettingsRepository {
+	return &TenantSettingsRepository{db: db, clock: clock.OrSystem(clk), logger: logger.With("component", "TenantSettingsRepository")}
+}
+
+func (r *TenantSettingsRepository) GetSettings(ctx context.Context, tenantID string) (*tenant.Settings, error) {
+	var s tenant.Settings
+	err := r.db.QueryRowContext(ctx, `
+        SELECT tenant_id, missed_payments, cure_payments, rate_limit_rps, rate_limit_burst, grace_days, late_fee, reminder_window_days,
+               COALESCE(updated_by, ''), updated_at
+        FROM tenant_settings
+        WHERE tenant_id = $1`, tenantID).Scan(&s.TenantID,
+		&s.Overrides.MissedPayments, &s.Overrides.CurePayments, &s.Overrides.RateLimitRPS, &s.Overrides.RateLimitBurst,
+		&s.Overrides.GraceDays, &s.Overrides.LateFee, &s.Overrides.ReminderWindowDays, &s.UpdatedBy, &s.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: settings of tenant %q", apperrors.ErrNotFound, tenantID)
+		}
+		r.logger.ErrorContext(ctx, "Failed to get tenant settings", slog.String("tenantID", tenantID), slog.Any("error", err))
+		return nil, fmt.Errorf("%w: failed to get tenant settings: %w", apperrors.ErrDatabase, err)
+	}
+	return &s, nil
+}
+
+func (r *TenantSettingsRepository) SaveSettings(ctx context.Context, s *tenant.Settings) error {
+	updatedAt := now(r.clock)
+	_, err := r.db.ExecContext(ctx, `
+        INSERT INTO tenant_settings (tenant_id, missed_payments, cure_payments, rate_limit_rps, rate_limit_burst, grace_days, late_fee, reminder_window_days,
+                                     updated_by, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), $10)
+        ON CONFLICT (tenant_id) DO UPDATE SET
+            missed_payments = excluded.missed_payments,
+            cure_payments = excluded.cure_payments,
+            rate_limit_rps = excluded.rate_limit_rps,
+            rate_limit_burst = excluded.rate_limit_burst,
+            grace_days = excluded.grace_days,
+            late_fee = excluded.late_fee,
+            reminder_window_days = excluded.reminder_window_days,
+            updated_by = excluded.updated_by,
+            updated_at = excluded.updated_at`, s.TenantID,
+		s.Overrides.MissedPayments, s.Overrides.CurePayments, s.Overrides.RateLimitRPS, s.Overrides.RateLimitBurst,
+		s.Overrides.GraceDays, s.Overrides.LateFee, s.Overrides.ReminderWindowDays, s.UpdatedBy, updatedAt)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to save tenant settings", slog.String("tenantID", s.TenantID), slog.Any("error", err))
+		return fmt.Errorf("%w: failed to save tenant settings: %w", apperrors.ErrDatabase, err)
+	}
+	s.UpdatedAt = updatedAt
+	return nil
+}
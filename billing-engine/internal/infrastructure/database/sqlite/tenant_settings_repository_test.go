@@ -0,0 +1,52 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"billing-engine/internal/domain/tenant"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTenantSettingsRepository(t *testing.T) {
+	db := openTestDB(t)
+	clk := clock.NewFake(day("2025-03-01"))
+	repo := NewTenantSettingsRepository(db, clk, testLogger)
+	ctx := context.Background()
+
+	_, err := repo.GetSettings(ctx, tenant.DefaultTenant)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+
+	missed, rps, grace, lateFee, window := 3, 2.5, 5, 15.5, 10
+	first := &tenant.Settings{TenantID: tenant.DefaultTenant, Overrides: tenant.Overrides{MissedPayments: &missed, RateLimitRPS: &rps,
+		GraceDays: &grace, LateFee: &lateFee, ReminderWindowDays: &window}, UpdatedBy: "ops"}
+	require.NoError(t, repo.SaveSettings(ctx, first))
+	got, err := repo.GetSettings(ctx, tenant.DefaultTenant)
+	require.NoError(t, err)
+	assert.Equal(t, first.Overrides, got.Overrides)
+	assert.Equal(t, "ops", got.UpdatedBy)
+	assert.True(t, got.UpdatedAt.Equal(day("2025-03-01")))
+
+	clk.Set(day("2025-03-02"))
+	burst := 7
+	require.NoError(t, repo.SaveSettings(ctx, &tenant.Settings{TenantID: tenant.DefaultTenant, Overrides: tenant.Overrides{RateLimitBurst: &burst}}))
+	got, err = repo.GetSettings(ctx, tenant.DefaultTenant)
+	require.NoError(t, err)
+	assert.Equal(t, tenant.Overrides{RateLimitBurst: &burst}, got.Overrides, "a save replaces every override")
+	assert.Empty(t, got.UpdatedBy)
+	assert.True(t, got.UpdatedAt.Equal(day("2025-03-02")))
+
+	other := &tenant.Settings{TenantID: "acme", Overrides: tenant.Overrides{GraceDays: &grace}}
+	require.NoError(t, repo.SaveSettings(ctx, other))
+	got, err = repo.GetSettings(ctx, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, other.Overrides, got.Overrides)
+	got, err = repo.GetSettings(ctx, tenant.DefaultTenant)
+	require.NoError(t, err)
+	assert.Equal(t, tenant.Overrides{RateLimitBurst: &burst}, got.Overrides, "each tenant keeps its own settings")
+}
@@ -0,0 +1,15 @@
+//go:build !sqlite
+
+package database
+
+import (
+	"billing-engine/internal/config"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+func openSQLite(context.Context, config.DatabaseConfig, clock.Clock, *slog.Logger) (*Repositories, error) {
+	return nil, fmt.Errorf("database driver %q is not compiled into this binary, rebuild with -tags sqlite", DriverSQLite)
+}
@@ -0,0 +1,19 @@
+//go:build !sqlite
+
+package database
+
+import (
+	"billing-engine/internal/config"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenSQLiteNotCompiled(t *testing.T) {
+	_, err := Open(context.Background(), config.DatabaseConfig{Driver: DriverSQLite, Path: "billing.db"}, clock.System(), testLogger)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "-tags sqlite")
+}
@@ -0,0 +1,40 @@
+//go:build sqlite
+
+package database
+
+import (
+	"billing-engine/internal/config"
+	"billing-engine/internal/infrastructure/database/sqlite"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"log/slog"
+)
+
+func openSQLite(ctx context.Context, cfg config.DatabaseConfig, clk clock.Clock, logger *slog.Logger) (*Repositories, error) {
+	db, err := sqlite.Open(ctx, cfg.Path, logger)
+	if err != nil {
+		return nil, err
+	}
+	loans := sqlite.NewLoanRepository(db, clk, logger)
+	return &Repositories{
+		Loans:        loans,
+		Snapshots:    loans,
+		Customers:    sqlite.NewCustomerRepository(db, clk, logger),
+		Contacts:     sqlite.NewContactRepository(db, clk, logger),
+		Notes:        sqlite.NewNoteRepository(db, clk, logger),
+		Documents:    sqlite.NewDocumentRepository(db, clk, logger),
+		Agreements:   sqlite.NewAgreementRepository(db, logger),
+		DirectDebits: sqlite.NewDirectDebitRepository(db, clk, logger),
+		Collections:  sqlite.NewCollectionsRepository(db, clk, logger),
+		Events:       sqlite.NewEventLogRepository(db, logger),
+		Summaries:    sqlite.NewSummaryRepository(db, clk, logger),
+		Integrity:    sqlite.NewIntegrityRepository(db, logger),
+		Tenants:      sqlite.NewTenantSettingsRepository(db, clk, logger),
+		Archive:      loans,
+		Partitions:   loans,
+		Jobs:         sqlite.NewJobRepository(db, logger),
+		JobRuns:      sqlite.NewJobRunRepository(db, logger),
+		JobLocks:     sqlite.NewJobLockRepository(db, logger),
+		close:        func() { _ = db.Close() },
+	}, nil
+}
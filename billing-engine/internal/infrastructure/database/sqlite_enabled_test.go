@@ -0,0 +1,34 @@
+//go:build sqlite
+
+package database
+
+import (
+	"billing-engine/internal/config"
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenSQLite(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "billing.db")
+
+	repos, err := Open(ctx, config.DatabaseConfig{Driver: DriverSQLite, Path: path}, clock.System(), testLogger)
+	require.NoError(t, err)
+	cust := customer.NewCustomer("Jane Doe", "1 Main St")
+	require.NoError(t, repos.Customers.Save(ctx, cust))
+	repos.Close()
+
+	// The file keeps the data and reopening it leaves the schema alone.
+	repos, err = Open(ctx, config.DatabaseConfig{Driver: DriverSQLite, Path: path}, clock.System(), testLogger)
+	require.NoError(t, err)
+	defer repos.Close()
+	found, err := repos.Customers.FindByID(ctx, cust.CustomerID)
+	require.NoError(t, err)
+	assert.Equal(t, cust.PublicID, found.PublicID)
+}
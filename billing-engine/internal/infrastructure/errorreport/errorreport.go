@@ -0,0 +1,127 @@
+// Package errorreport forwards failures to an error tracker: handler panics,
+// through the recovery middleware, and every record logged at error level,
+// through Handler.
+package errorreport
+
+import (
+	"billing-engine/internal/config"
+	"billing-engine/internal/pkg/buildinfo"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	LevelError = "error"
+	LevelFatal = "fatal"
+)
+
+// Event is one failure to report. Level is LevelError unless set; Method
+// and URL are empty outside a request.
+type Event struct {
+	Level     string
+	Message   string
+	Stack     string
+	RequestID string
+	Method    string
+	URL       string
+	Time      time.Time
+	// Tags are indexed by the tracker, such as the name of a failed job.
+	Tags map[string]string
+}
+
+func (e Event) level() string {
+	if e.Level == "" {
+		return LevelError
+	}
+	return e.Level
+}
+
+// tags are e's tags with the request ID, when there is one.
+func (e Event) tags() map[string]string {
+	tags := make(map[string]string, len(e.Tags)+1)
+	for key, value := range e.Tags {
+		tags[key] = value
+	}
+	if e.RequestID != "" {
+		tags["request_id"] = e.RequestID
+	}
+	return tags
+}
+
+type Reporter interface {
+	Report(ctx context.Context, event Event) error
+}
+
+// New returns the reporter cfg selects, or nil when cfg names no provider.
+// Without a provider a DSN selects Sentry. The reports go through client;
+// nil means one with cfg.Timeout.
+func New(cfg config.ErrorReportingConfig, client *http.Client) (Reporter, error) {
+	if client == nil {
+		client = &http.Client{Timeout: cfg.Timeout}
+	}
+	tags := releaseTags{environment: cfg.Environment, release: cfg.Release}
+	if tags.release == "" {
+		tags.release = buildinfo.Get().Release()
+	}
+	provider := strings.ToLower(cfg.Provider)
+	if provider == "" && cfg.DSN != "" {
+		provider = "sentry"
+	}
+	var (
+		reporter Reporter
+		err      error
+	)
+	switch provider {
+	case "":
+		return nil, nil
+	case "sentry":
+		reporter, err = newSentryReporter(cfg.DSN, tags, client)
+	case "rollbar":
+		reporter, err = newRollbarReporter(cfg.AccessToken, tags, client)
+	default:
+		return nil, fmt.Errorf("unknown error reporting provider %q, want sentry or rollbar", cfg.Provider)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return reporter, nil
+}
+
+// releaseTags say where a report comes from.
+type releaseTags struct {
+	environment string
+	release     string
+}
+
+// post sends body as JSON to url and fails on any status but 2xx.
+func post(ctx context.Context, client *http.Client, url string, header http.Header, body any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("encode error report: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("build error report request: %w", err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("send error report: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("send error report: tracker answered %s", resp.Status)
+	}
+	return nil
+}
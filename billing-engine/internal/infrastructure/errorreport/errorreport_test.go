@@ -0,0 +1,104 @@
+package errorreport
+
+import (
+	"billing-engine/internal/config"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testEvent = Event{
+	Message: "panic: runtime error: index out of range", Stack: "goroutine 1 [running]:", RequestID: "host/abc-000001",
+	Method: http.MethodGet, URL: "/loans/7", Time: time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC),
+}
+
+type capture struct {
+	path   string
+	header http.Header
+	body   map[string]any
+}
+
+func newTracker(t *testing.T, status int) (*httptest.Server, *capture) {
+	t.Helper()
+	got := &capture{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.path, got.header = r.URL.Path, r.Header
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&got.body))
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, got
+}
+
+func TestNew(t *testing.T) {
+	reporter, err := New(config.ErrorReportingConfig{}, nil)
+	require.NoError(t, err)
+	assert.Nil(t, reporter)
+
+	for _, cfg := range []config.ErrorReportingConfig{
+		{Provider: "bugsnag"},
+		{Provider: "sentry", DSN: "not a dsn"},
+		{Provider: "sentry", DSN: "https://o1.ingest.sentry.io/42"},
+		{Provider: "rollbar"},
+	} {
+		reporter, err := New(cfg, nil)
+		assert.Error(t, err, cfg)
+		assert.Nil(t, reporter, cfg)
+	}
+}
+
+func TestSentryReporter(t *testing.T) {
+	server, got := newTracker(t, http.StatusOK)
+	dsn := strings.Replace(server.URL, "://", "://public-key@", 1) + "/42"
+	reporter, err := New(config.ErrorReportingConfig{DSN: dsn, Environment: "staging", Release: "v1.4.0"}, server.Client())
+	require.NoError(t, err)
+
+	event := testEvent
+	event.Level, event.Tags = LevelFatal, map[string]string{"job_name": "DelinquencyUpdate"}
+	require.NoError(t, reporter.Report(context.Background(), event))
+
+	assert.Equal(t, "/api/42/store/", got.path)
+	assert.Contains(t, got.header.Get("X-Sentry-Auth"), "sentry_key=public-key")
+	assert.Equal(t, "staging", got.body["environment"])
+	assert.Equal(t, "v1.4.0", got.body["release"])
+	assert.Equal(t, "fatal", got.body["level"])
+	assert.Len(t, got.body["event_id"], 32)
+	assert.Equal(t, map[string]any{"formatted": testEvent.Message}, got.body["message"])
+	assert.Equal(t, map[string]any{"request_id": testEvent.RequestID, "job_name": "DelinquencyUpdate"}, got.body["tags"])
+	assert.Equal(t, map[string]any{"stack": testEvent.Stack}, got.body["extra"])
+}
+
+func TestRollbarReporter(t *testing.T) {
+	server, got := newTracker(t, http.StatusOK)
+	reporter, err := newRollbarReporter("post-token", releaseTags{environment: "staging", release: "v1.4.0"}, server.Client())
+	require.NoError(t, err)
+	reporter.itemURL = server.URL + "/api/1/item/"
+
+	require.NoError(t, reporter.Report(context.Background(), testEvent))
+
+	assert.Equal(t, "post-token", got.header.Get("X-Rollbar-Access-Token"))
+	data := got.body["data"].(map[string]any)
+	assert.Equal(t, "staging", data["environment"])
+	assert.Equal(t, "v1.4.0", data["code_version"])
+	assert.Equal(t, "error", data["level"])
+	assert.Equal(t, float64(testEvent.Time.Unix()), data["timestamp"])
+	assert.Equal(t, map[string]any{"body": testEvent.Message, "stack": testEvent.Stack},
+		data["body"].(map[string]any)["message"])
+	assert.Equal(t, map[string]any{"request_id": testEvent.RequestID}, data["custom"])
+}
+
+func TestReportFailure(t *testing.T) {
+	server, _ := newTracker(t, http.StatusTooManyRequests)
+	reporter, err := newRollbarReporter("post-token", releaseTags{environment: "staging", release: "v1.4.0"}, server.Client())
+	require.NoError(t, err)
+	reporter.itemURL = server.URL
+
+	assert.ErrorContains(t, reporter.Report(context.Background(), testEvent), "429")
+}
@@ -0,0 +1,140 @@
+package errorreport
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// maxInFlight bounds the reports Handler sends at once. Records logged while
+// that many are on their way are not reported, so a flood of errors cannot
+// pile up goroutines waiting on a slow tracker.
+const maxInFlight = 8
+
+type reportedKey struct{}
+
+// MarkReported returns a context whose error records Handler leaves alone,
+// for a caller that reports the failure itself with more detail, as the
+// recovery middleware does with the stack.
+func MarkReported(ctx context.Context) context.Context {
+	return context.WithValue(ctx, reportedKey{}, true)
+}
+
+func isReported(ctx context.Context) bool {
+	reported, _ := ctx.Value(reportedKey{}).(bool)
+	return reported
+}
+
+// Handler passes every record to the handler it wraps and reports those at
+// error level or above. The record's message and error attribute become the
+// event's message; its request_id and stack attributes fill those fields and
+// the other attributes become tags, so a failed job is tagged with its
+// job_name. Reports that fail are logged at warning level, which is not
+// reported again.
+type Handler struct {
+	next     slog.Handler
+	reporter Reporter
+	attrs    []slog.Attr
+	group    string
+	inFlight chan struct{}
+}
+
+func NewHandler(next slog.Handler, reporter Reporter) *Handler {
+	return &Handler{next: next, reporter: reporter, inFlight: make(chan struct{}, maxInFlight)}
+}
+
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *Handler) Handle(ctx context.Context, record slog.Record) error {
+	err := h.next.Handle(ctx, record)
+	if record.Level < slog.LevelError || isReported(ctx) {
+		return err
+	}
+	select {
+	case h.inFlight <- struct{}{}:
+	default:
+		return err
+	}
+	event := h.event(record)
+	go func() {
+		defer func() { <-h.inFlight }()
+		if reportErr := h.reporter.Report(context.WithoutCancel(ctx), event); reportErr != nil {
+			warning := slog.NewRecord(event.Time, slog.LevelWarn, "Failed to report error", 0)
+			warning.AddAttrs(slog.Any("error", reportErr))
+			_ = h.next.Handle(ctx, warning)
+		}
+	}()
+	return err
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.next = h.next.WithAttrs(attrs)
+	clone.attrs = append(clone.attrs[:len(clone.attrs):len(clone.attrs)], qualify(h.group, attrs)...)
+	return &clone
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.next = h.next.WithGroup(name)
+	clone.group = qualifyKey(h.group, name)
+	return &clone
+}
+
+func (h *Handler) event(record slog.Record) Event {
+	event := Event{Level: LevelError, Message: record.Message, Time: record.Time, Tags: make(map[string]string)}
+	var cause string
+	add := func(a slog.Attr) {
+		value := a.Value.Resolve()
+		switch a.Key {
+		case "error":
+			cause = fmt.Sprint(value.Any())
+		case "request_id":
+			event.RequestID = value.String()
+		case "stack":
+			event.Stack = value.String()
+		default:
+			if value.Kind() != slog.KindGroup {
+				event.Tags[a.Key] = value.String()
+			}
+		}
+	}
+	for _, a := range h.attrs {
+		add(a)
+	}
+	var recordAttrs []slog.Attr
+	record.Attrs(func(a slog.Attr) bool {
+		recordAttrs = append(recordAttrs, a)
+		return true
+	})
+	for _, a := range qualify(h.group, recordAttrs) {
+		add(a)
+	}
+	if cause != "" {
+		event.Message += ": " + cause
+	}
+	return event
+}
+
+// qualify prefixes the keys of attrs with group, as the JSON handler nests
+// them.
+func qualify(group string, attrs []slog.Attr) []slog.Attr {
+	if group == "" {
+		return attrs
+	}
+	qualified := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		qualified[i] = slog.Attr{Key: qualifyKey(group, a.Key), Value: a.Value}
+	}
+	return qualified
+}
+
+func qualifyKey(group, key string) string {
+	return strings.TrimPrefix(group+"."+key, ".")
+}
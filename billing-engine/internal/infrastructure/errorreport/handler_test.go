@@ -0,0 +1,108 @@
+package errorreport
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// syncBuffer is written by the reports that fail in the background.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func (b *syncBuffer) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf.Reset()
+}
+
+type reporterFunc func(context.Context, Event) error
+
+func (f reporterFunc) Report(ctx context.Context, event Event) error { return f(ctx, event) }
+
+func TestHandler(t *testing.T) {
+	var logs syncBuffer
+	reported := make(chan Event, 4)
+	var fail atomic.Bool
+	logger := slog.New(NewHandler(slog.NewJSONHandler(&logs, nil), reporterFunc(func(_ context.Context, event Event) error {
+		reported <- event
+		if fail.Load() {
+			return errors.New("tracker is down")
+		}
+		return nil
+	})))
+	next := func(t *testing.T) Event {
+		t.Helper()
+		select {
+		case event := <-reported:
+			return event
+		case <-time.After(time.Second):
+			t.Fatal("nothing was reported")
+			return Event{}
+		}
+	}
+
+	t.Run("reports error records with their attributes as tags", func(t *testing.T) {
+		logger.With("job_name", "DelinquencyUpdate").WithGroup("run").
+			Error("Batch job finished with error", slog.Any("error", errors.New("deadlock")), slog.Int64("id", 7))
+
+		event := next(t)
+		assert.Equal(t, LevelError, event.Level)
+		assert.Equal(t, "Batch job finished with error", event.Message)
+		assert.Equal(t, map[string]string{"job_name": "DelinquencyUpdate", "run.id": "7", "run.error": "deadlock"}, event.Tags)
+		assert.Contains(t, logs.String(), "Batch job finished with error")
+	})
+
+	t.Run("takes the message, request ID and stack from their attributes", func(t *testing.T) {
+		logger.Error("Failed to load loan", "error", errors.New("deadlock"), "request_id", "host/abc-1", "stack", "goroutine 1")
+
+		event := next(t)
+		assert.Equal(t, "Failed to load loan: deadlock", event.Message)
+		assert.Equal(t, "host/abc-1", event.RequestID)
+		assert.Equal(t, "goroutine 1", event.Stack)
+		assert.Empty(t, event.Tags)
+	})
+
+	t.Run("skips lower levels and records reported already", func(t *testing.T) {
+		logger.Warn("Payment retried")
+		logger.ErrorContext(MarkReported(context.Background()), "Handler panicked")
+		logger.Error("Sent")
+
+		assert.Equal(t, "Sent", next(t).Message)
+	})
+
+	t.Run("logs a failed report as a warning", func(t *testing.T) {
+		fail.Store(true)
+		defer fail.Store(false)
+		logs.Reset()
+
+		logger.Error("Failed to queue batch job")
+		next(t)
+
+		require.Eventually(t, func() bool { return strings.Contains(logs.String(), "Failed to report error") }, time.Second, 5*time.Millisecond)
+		assert.Contains(t, logs.String(), `"level":"WARN"`)
+	})
+}
@@ -0,0 +1,49 @@
+package errorreport
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+const rollbarItemURL = "https://api.rollbar.com/api/1/item/"
+
+// rollbarReporter posts items to Rollbar with a project access token that
+// has the post_server_item scope.
+type rollbarReporter struct {
+	itemURL string
+	token   string
+	tags    releaseTags
+	client  *http.Client
+}
+
+func newRollbarReporter(token string, tags releaseTags, client *http.Client) (*rollbarReporter, error) {
+	if token == "" {
+		return nil, fmt.Errorf("rollbar error reporting needs an access token")
+	}
+	return &rollbarReporter{itemURL: rollbarItemURL, token: token, tags: tags, client: client}, nil
+}
+
+// rollbarLevels are Rollbar's names for the event levels.
+var rollbarLevels = map[string]string{LevelError: "error", LevelFatal: "critical"}
+
+func (r *rollbarReporter) Report(ctx context.Context, event Event) error {
+	data := map[string]any{
+		"environment": r.tags.environment,
+		"level":       rollbarLevels[event.level()],
+		"timestamp":   event.Time.Unix(),
+		"platform":    "go",
+		"language":    "go",
+		"body": map[string]any{
+			"message": map[string]string{"body": event.Message, "stack": event.Stack},
+		},
+		"custom": event.tags(),
+	}
+	if r.tags.release != "" {
+		data["code_version"] = r.tags.release
+	}
+	if event.Method != "" {
+		data["request"] = map[string]string{"method": event.Method, "url": event.URL}
+	}
+	return post(ctx, r.client, r.itemURL, http.Header{"X-Rollbar-Access-Token": {r.token}}, map[string]any{"data": data})
+}
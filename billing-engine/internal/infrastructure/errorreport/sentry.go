@@ -0,0 +1,62 @@
+package errorreport
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// sentryReporter posts events to Sentry's store endpoint for the project a
+// DSN such as https://<key>@o1.ingest.sentry.io/<project> names.
+type sentryReporter struct {
+	storeURL string
+	auth     string
+	tags     releaseTags
+	client   *http.Client
+}
+
+func newSentryReporter(dsn string, tags releaseTags, client *http.Client) (*sentryReporter, error) {
+	u, err := url.Parse(dsn)
+	if err != nil || u.Host == "" || u.User.Username() == "" {
+		return nil, fmt.Errorf("invalid Sentry DSN, want https://<key>@<host>/<project>")
+	}
+	project := path.Base(u.Path)
+	if project == "." || project == "/" {
+		return nil, fmt.Errorf("invalid Sentry DSN: no project ID")
+	}
+	store := url.URL{Scheme: u.Scheme, Host: u.Host, Path: path.Join(path.Dir(u.Path), "api", project, "store") + "/"}
+	return &sentryReporter{
+		storeURL: store.String(),
+		auth:     fmt.Sprintf("Sentry sentry_version=7, sentry_client=billing-engine/1.0, sentry_key=%s", u.User.Username()),
+		tags:     tags,
+		client:   client,
+	}, nil
+}
+
+func (s *sentryReporter) Report(ctx context.Context, event Event) error {
+	body := map[string]any{
+		"event_id":    strings.ReplaceAll(uuid.NewString(), "-", ""),
+		"timestamp":   event.Time.UTC().Format("2006-01-02T15:04:05.000Z"),
+		"level":       event.level(),
+		"platform":    "go",
+		"logger":      "billing-engine",
+		"environment": s.tags.environment,
+		"message":     map[string]string{"formatted": event.Message},
+		"tags":        event.tags(),
+	}
+	if s.tags.release != "" {
+		body["release"] = s.tags.release
+	}
+	if event.Method != "" {
+		body["request"] = map[string]string{"method": event.Method, "url": event.URL}
+	}
+	if event.Stack != "" {
+		body["extra"] = map[string]string{"stack": event.Stack}
+	}
+	return post(ctx, s.client, s.storeURL, http.Header{"X-Sentry-Auth": {s.auth}}, body)
+}
@@ -0,0 +1,157 @@
+// Package esign connects loan agreements to an e-signature provider: it
+// sends the envelopes and authenticates the callbacks the provider makes as
+// they are signed, declined or voided.
+package esign
+
+import (
+	"billing-engine/internal/config"
+	"billing-engine/internal/domain/agreement"
+	"billing-engine/internal/pkg/apperrors"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// SignatureHeader carries the hex HMAC-SHA256 of a callback's body, keyed
+// with the callback secret and prefixed with "sha256=".
+const SignatureHeader = "X-Signature"
+
+// New returns the provider cfg selects, or nil when cfg names none. The
+// requests go through client; nil means one with cfg.Timeout.
+func New(cfg config.ESignConfig, client *http.Client) (agreement.Provider, error) {
+	if client == nil {
+		client = &http.Client{Timeout: cfg.Timeout}
+	}
+	switch provider := strings.ToLower(cfg.Provider); provider {
+	case "":
+		return nil, nil
+	case "http":
+		return newHTTPProvider(cfg, client)
+	default:
+		return nil, fmt.Errorf("unknown e-signature provider %q, want http", cfg.Provider)
+	}
+}
+
+// HTTPProvider speaks a plain JSON API: POST {url}/envelopes creates an
+// envelope and answers with its envelopeId, and callbacks post the
+// envelope's envelopeId and status, signed in SignatureHeader.
+type HTTPProvider struct {
+	envelopesURL string
+	apiKey       string
+	secret       []byte
+	client       *http.Client
+}
+
+var _ agreement.Provider = (*HTTPProvider)(nil)
+
+func newHTTPProvider(cfg config.ESignConfig, client *http.Client) (*HTTPProvider, error) {
+	base, err := url.Parse(strings.TrimSuffix(cfg.URL, "/"))
+	if err != nil || base.Host == "" {
+		return nil, fmt.Errorf("invalid e-signature provider URL %q", cfg.URL)
+	}
+	if cfg.CallbackSecret == "" {
+		// Without it anyone could mark an agreement signed.
+		return nil, fmt.Errorf("e-signature callback secret is empty in configuration")
+	}
+	return &HTTPProvider{
+		envelopesURL: base.String() + "/envelopes",
+		apiKey:       cfg.APIKey,
+		secret:       []byte(cfg.CallbackSecret),
+		client:       client,
+	}, nil
+}
+
+func (p *HTTPProvider) Name() string {
+	return "http"
+}
+
+type envelopeRequest struct {
+	Reference   string `json:"reference"`
+	SignerName  string `json:"signerName"`
+	SignerEmail string `json:"signerEmail"`
+	FileName    string `json:"fileName"`
+	ContentType string `json:"contentType"`
+	// Content is base64 encoded by encoding/json.
+	Content []byte `json:"content"`
+}
+
+type envelopeResponse struct {
+	EnvelopeID string `json:"envelopeId"`
+}
+
+func (p *HTTPProvider) Send(ctx context.Context, envelope agreement.Envelope) (string, error) {
+	payload, err := json.Marshal(envelopeRequest{
+		Reference: envelope.Reference, SignerName: envelope.Signer.Name, SignerEmail: envelope.Signer.Email,
+		FileName: envelope.FileName, ContentType: envelope.ContentType, Content: envelope.Content,
+	})
+	if err != nil {
+		return "", fmt.Errorf("encode envelope: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.envelopesURL, bytes.NewReader(payload))
+	if err != nil {
+		return "", fmt.Errorf("build envelope request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("send envelope: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return "", fmt.Errorf("send envelope: provider answered %s", resp.Status)
+	}
+	var created envelopeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return "", fmt.Errorf("decode envelope: %w", err)
+	}
+	if created.EnvelopeID == "" {
+		return "", fmt.Errorf("send envelope: provider answered without an envelope ID")
+	}
+	return created.EnvelopeID, nil
+}
+
+type callbackRequest struct {
+	EnvelopeID string `json:"envelopeId"`
+	Status     string `json:"status"`
+}
+
+func (p *HTTPProvider) ParseCallback(body []byte, header http.Header) (*agreement.StatusUpdate, error) {
+	signature, ok := strings.CutPrefix(header.Get(SignatureHeader), "sha256=")
+	got, err := hex.DecodeString(signature)
+	if !ok || err != nil || !hmac.Equal(got, Sign(p.secret, body)) {
+		return nil, fmt.Errorf("%w: callback signature does not match", apperrors.ErrUnauthorized)
+	}
+	var callback callbackRequest
+	if err := json.Unmarshal(body, &callback); err != nil {
+		return nil, fmt.Errorf("%w: malformed callback: %w", apperrors.ErrInvalidArgument, err)
+	}
+	if callback.EnvelopeID == "" {
+		return nil, fmt.Errorf("%w: callback names no envelope", apperrors.ErrInvalidArgument)
+	}
+	status, err := agreement.ParseStatus(callback.Status)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", apperrors.ErrInvalidArgument, err)
+	}
+	return &agreement.StatusUpdate{EnvelopeID: callback.EnvelopeID, Status: status}, nil
+}
+
+// Sign is the HMAC-SHA256 of body that a callback is signed with.
+func Sign(secret, body []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return mac.Sum(nil)
+}
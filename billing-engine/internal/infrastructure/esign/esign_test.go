@@ -0,0 +1,125 @@
+package esign
+
+import (
+	"billing-engine/internal/config"
+	"billing-engine/internal/domain/agreement"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	provider, err := New(config.ESignConfig{}, nil)
+	require.NoError(t, err)
+	assert.Nil(t, provider)
+
+	for _, cfg := range []config.ESignConfig{
+		{Provider: "docusign"},
+		{Provider: "http", URL: "esign:8080", CallbackSecret: "s3cret"},
+		{Provider: "http", URL: "http://esign:8080"},
+	} {
+		provider, err := New(cfg, nil)
+		assert.Error(t, err, cfg)
+		assert.Nil(t, provider, cfg)
+	}
+}
+
+func newTestProvider(t *testing.T, handler http.HandlerFunc) agreement.Provider {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	provider, err := New(config.ESignConfig{Provider: "HTTP", URL: server.URL + "/", APIKey: "key-1", CallbackSecret: "s3cret"}, server.Client())
+	require.NoError(t, err)
+	return provider
+}
+
+var testEnvelope = agreement.Envelope{
+	LoanID: 42, Reference: "5f0c2a1e-0000-4000-8000-000000000042", Signer: agreement.Signer{Name: "Budi", Email: "budi@example.com"},
+	FileName: "loan-agreement-42.pdf", ContentType: "application/pdf", Content: []byte("%PDF-1.4"),
+}
+
+func TestHTTPProviderSend(t *testing.T) {
+	t.Run("posts the envelope and returns its ID", func(t *testing.T) {
+		var gotPath, gotAuth string
+		var got envelopeRequest
+		provider := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+			gotPath, gotAuth = r.URL.Path, r.Header.Get("Authorization")
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+			w.WriteHeader(http.StatusCreated)
+			_, _ = io.WriteString(w, `{"envelopeId":"env-1"}`)
+		})
+
+		envelopeID, err := provider.Send(context.Background(), testEnvelope)
+
+		require.NoError(t, err)
+		assert.Equal(t, "env-1", envelopeID)
+		assert.Equal(t, "/envelopes", gotPath)
+		assert.Equal(t, "Bearer key-1", gotAuth)
+		assert.Equal(t, "budi@example.com", got.SignerEmail)
+		assert.Equal(t, testEnvelope.Reference, got.Reference)
+		assert.Equal(t, testEnvelope.Content, got.Content)
+		assert.Equal(t, "http", provider.Name())
+	})
+
+	t.Run("fails when the provider refuses it", func(t *testing.T) {
+		provider := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		})
+
+		_, err := provider.Send(context.Background(), testEnvelope)
+
+		assert.ErrorContains(t, err, "502")
+	})
+
+	t.Run("fails without an envelope ID", func(t *testing.T) {
+		provider := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, `{}`)
+		})
+
+		_, err := provider.Send(context.Background(), testEnvelope)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestHTTPProviderParseCallback(t *testing.T) {
+	provider := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {})
+	signed := func(body string) http.Header {
+		return http.Header{SignatureHeader: {"sha256=" + hex.EncodeToString(Sign([]byte("s3cret"), []byte(body)))}}
+	}
+
+	body := `{"envelopeId":"env-1","status":"completed"}`
+	update, err := provider.ParseCallback([]byte(body), signed(body))
+	require.NoError(t, err)
+	assert.Equal(t, &agreement.StatusUpdate{EnvelopeID: "env-1", Status: agreement.StatusCompleted}, update)
+
+	tests := []struct {
+		name   string
+		body   string
+		header http.Header
+		want   error
+	}{
+		{name: "unsigned", body: body, header: http.Header{}, want: apperrors.ErrUnauthorized},
+		{name: "signed with another secret", body: body,
+			header: http.Header{SignatureHeader: {"sha256=" + hex.EncodeToString(Sign([]byte("other"), []byte(body)))}}, want: apperrors.ErrUnauthorized},
+		{name: "signed for another body", body: body, header: signed(`{"envelopeId":"env-1","status":"DECLINED"}`), want: apperrors.ErrUnauthorized},
+		{name: "malformed", body: `{`, header: signed(`{`), want: apperrors.ErrInvalidArgument},
+		{name: "unknown status", body: `{"envelopeId":"env-1","status":"LOST"}`, header: signed(`{"envelopeId":"env-1","status":"LOST"}`), want: apperrors.ErrInvalidArgument},
+		{name: "no envelope", body: `{"status":"COMPLETED"}`, header: signed(`{"status":"COMPLETED"}`), want: apperrors.ErrInvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := provider.ParseCallback([]byte(tt.body), tt.header)
+			assert.ErrorIs(t, err, tt.want)
+		})
+	}
+}
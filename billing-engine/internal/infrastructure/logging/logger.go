@@ -2,6 +2,7 @@ package logging
 
 import (
 	"billing-engine/internal/config"
+	"billing-engine/internal/infrastructure/errorreport"
 	"log/slog"
 	"os"
 	"strings"
@@ -9,7 +10,10 @@ import (
 	"github.com/go-chi/traceid"
 )
 
-func NewLogger(cfg config.LoggerConfig) *slog.Logger {
+// NewLogger returns the JSON logger every component writes to and makes it
+// the default. Records at error level also go to reporter unless that is
+// nil.
+func NewLogger(cfg config.LoggerConfig, reporter errorreport.Reporter) *slog.Logger {
 	var level slog.Level
 	switch strings.ToLower(cfg.Level) {
 	case "debug":
@@ -29,6 +33,9 @@ func NewLogger(cfg config.LoggerConfig) *slog.Logger {
 		AddSource: level == slog.LevelDebug,
 	}
 	var handler slog.Handler = slog.NewJSONHandler(os.Stdout, opts)
+	if reporter != nil {
+		handler = errorreport.NewHandler(handler, reporter)
+	}
 	handler = traceid.LogHandler(handler)
 	logger := slog.New(handler)
 	slog.SetDefault(logger)
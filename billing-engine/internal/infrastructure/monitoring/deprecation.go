@@ -0,0 +1,106 @@
+package monitoring
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	// OtherClients is the client the calls of every client past
+	// maxDeprecatedCallers are counted under.
+	OtherClients = "other"
+
+	// maxDeprecatedCallers bounds the route and client pairs a
+	// DeprecationTracker holds, whatever the number of clients.
+	maxDeprecatedCallers = 10000
+)
+
+// deprecatedRequests has no client label, for the same reason as the rate
+// limiter's counter; DeprecationTracker names the clients instead.
+var deprecatedRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "billing_engine_deprecated_requests_total",
+	Help: "Total number of requests to deprecated routes.",
+}, []string{"method", "route"})
+
+// DeprecationTracker counts the calls to deprecated routes per client since
+// the process started, so that the clients still calling a route can be
+// found before it is removed.
+type DeprecationTracker struct {
+	mu    sync.Mutex
+	calls map[deprecatedCall]*DeprecatedUsage
+}
+
+type deprecatedCall struct {
+	method, route, client string
+}
+
+// DeprecatedUsage is how often one client called one deprecated route.
+type DeprecatedUsage struct {
+	Method    string
+	Route     string
+	Client    string
+	Requests  uint64
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+func NewDeprecationTracker() *DeprecationTracker {
+	return &DeprecationTracker{calls: map[deprecatedCall]*DeprecatedUsage{}}
+}
+
+// Record counts a call by client to route at. Once maxDeprecatedCallers
+// pairs are held, calls from pairs not seen before count under
+// OtherClients.
+func (t *DeprecationTracker) Record(method, route, client string, at time.Time) {
+	deprecatedRequests.WithLabelValues(method, route).Inc()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := deprecatedCall{method, route, client}
+	u, ok := t.calls[key]
+	if !ok && len(t.calls) >= maxDeprecatedCallers {
+		key.client = OtherClients
+		u, ok = t.calls[key]
+	}
+	if !ok {
+		u = &DeprecatedUsage{Method: method, Route: route, Client: key.client, FirstSeen: at}
+		t.calls[key] = u
+	}
+	u.Requests++
+	if at.After(u.LastSeen) {
+		u.LastSeen = at
+	}
+}
+
+// Usage returns up to n route and client pairs with the most calls, busiest
+// first.
+func (t *DeprecationTracker) Usage(n int) []DeprecatedUsage {
+	t.mu.Lock()
+	usage := make([]DeprecatedUsage, 0, len(t.calls))
+	for _, u := range t.calls {
+		usage = append(usage, *u)
+	}
+	t.mu.Unlock()
+
+	sort.Slice(usage, func(i, j int) bool {
+		a, b := usage[i], usage[j]
+		if a.Requests != b.Requests {
+			return a.Requests > b.Requests
+		}
+		if a.Route != b.Route {
+			return a.Route < b.Route
+		}
+		if a.Method != b.Method {
+			return a.Method < b.Method
+		}
+		return a.Client < b.Client
+	})
+	if len(usage) > n {
+		usage = usage[:n]
+	}
+	return usage
+}
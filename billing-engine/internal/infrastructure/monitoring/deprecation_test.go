@@ -0,0 +1,47 @@
+package monitoring
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeprecationTracker(t *testing.T) {
+	start := time.Date(2025, 4, 2, 9, 0, 0, 0, time.UTC)
+
+	t.Run("counts per route and client, busiest first", func(t *testing.T) {
+		tracker := NewDeprecationTracker()
+		for i := range 3 {
+			tracker.Record("GET", "/loans/{loanID}", "collections-batch", start.Add(time.Duration(i)*time.Minute))
+		}
+		tracker.Record("GET", "/loans/{loanID}", "203.0.113.7", start)
+		tracker.Record("POST", "/loans/{loanID}/payments", "collections-batch", start)
+
+		usage := tracker.Usage(10)
+
+		require.Len(t, usage, 3)
+		assert.Equal(t, DeprecatedUsage{Method: "GET", Route: "/loans/{loanID}", Client: "collections-batch",
+			Requests: 3, FirstSeen: start, LastSeen: start.Add(2 * time.Minute)}, usage[0])
+		assert.Equal(t, "203.0.113.7", usage[1].Client, "ties are sorted by route, method and client")
+		assert.Equal(t, "/loans/{loanID}/payments", usage[2].Route)
+		assert.Len(t, tracker.Usage(1), 1)
+	})
+
+	t.Run("counts clients past the bound together", func(t *testing.T) {
+		tracker := NewDeprecationTracker()
+		for i := range maxDeprecatedCallers + 5 {
+			tracker.Record("GET", "/customers", fmt.Sprintf("client-%d", i), start)
+		}
+		tracker.Record("GET", "/customers", "client-0", start)
+
+		usage := tracker.Usage(2)
+
+		assert.Equal(t, OtherClients, usage[0].Client)
+		assert.Equal(t, uint64(5), usage[0].Requests)
+		assert.Equal(t, "client-0", usage[1].Client, "clients seen before keep their own count")
+		assert.Equal(t, uint64(2), usage[1].Requests)
+	})
+}
@@ -17,8 +17,45 @@ type DBMetrics struct {
 }
 
 type BusinessMetrics struct {
-	LoansCreatedTotal      prometheus.Counter
-	PaymentsProcessedTotal *prometheus.CounterVec
+	LoansCreatedTotal       prometheus.Counter
+	PaymentsProcessedTotal  *prometheus.CounterVec
+	PaymentsReceivedTotal   *prometheus.CounterVec
+	PaymentsReceivedAmount  *prometheus.CounterVec
+	DelinquencyChangesTotal *prometheus.CounterVec
+}
+
+type MessagingMetrics struct {
+	PublishedTotal  *prometheus.CounterVec
+	EventLogBacklog prometheus.Gauge
+}
+
+type JobMetrics struct {
+	Duration       *prometheus.HistogramVec
+	LastSuccess    *prometheus.GaugeVec
+	Running        *prometheus.GaugeVec
+	ItemsProcessed *prometheus.GaugeVec
+	ItemsTotal     *prometheus.GaugeVec
+	Missed         *prometheus.GaugeVec
+	Overrunning    *prometheus.GaugeVec
+	AlertsTotal    *prometheus.CounterVec
+	SkippedTotal   *prometheus.CounterVec
+}
+
+type AsyncJobMetrics struct {
+	InProgress    *prometheus.GaugeVec
+	RejectedTotal *prometheus.CounterVec
+	FinishedTotal *prometheus.CounterVec
+}
+
+type CollectionsMetrics struct {
+	QueueSize      *prometheus.GaugeVec
+	ResolutionTime *prometheus.HistogramVec
+	ActionsTotal   *prometheus.CounterVec
+	EscalatedTotal *prometheus.CounterVec
+}
+
+type IntegrityMetrics struct {
+	Violations *prometheus.GaugeVec
 }
 
 var (
@@ -65,6 +102,176 @@ var (
 			},
 			[]string{"status"},
 		),
+		PaymentsReceivedTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "billing_engine_payments_received_total",
+				Help: "Total number of payments applied to a loan, by payment channel.",
+			},
+			[]string{"channel"},
+		),
+		PaymentsReceivedAmount: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "billing_engine_payments_received_amount_total",
+				Help: "Total amount of the payments applied to a loan, by payment channel.",
+			},
+			[]string{"channel"},
+		),
+		DelinquencyChangesTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "billing_engine_delinquency_changes_total",
+				Help: "Total number of customers the delinquency job flagged (became_delinquent) or cleared (cured).",
+			},
+			[]string{"direction"},
+		),
+	}
+
+	Messaging = MessagingMetrics{
+		PublishedTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "billing_engine_events_published_total",
+				Help: "Total number of event publish attempts to RabbitMQ by outcome: confirmed, nacked (retried), unroutable or failed.",
+			},
+			[]string{"type", "outcome"},
+		),
+		EventLogBacklog: promauto.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "billing_engine_event_log_unpublished",
+				Help: "Events in the event log the broker has not accepted yet, as of the last check.",
+			},
+		),
+	}
+
+	Jobs = JobMetrics{
+		Duration: promauto.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "billing_engine_batch_job_duration_seconds",
+				Help:    "Histogram of scheduled batch job run times by outcome.",
+				Buckets: []float64{1, 5, 15, 30, 60, 300, 900, 1800, 3600},
+			},
+			[]string{"job", "status"},
+		),
+		LastSuccess: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "billing_engine_batch_job_last_success_timestamp_seconds",
+				Help: "Unix time at which each scheduled batch job last finished without error.",
+			},
+			[]string{"job"},
+		),
+		Running: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "billing_engine_batch_job_running",
+				Help: "Whether each scheduled batch job is running on this instance.",
+			},
+			[]string{"job"},
+		),
+		ItemsProcessed: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "billing_engine_batch_job_items_processed",
+				Help: "Items the current or last run of each scheduled batch job on this instance has processed.",
+			},
+			[]string{"job"},
+		),
+		ItemsTotal: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "billing_engine_batch_job_items_total",
+				Help: "Items the current or last run of each scheduled batch job on this instance works through, zero when unknown.",
+			},
+			[]string{"job"},
+		),
+		Missed: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "billing_engine_batch_job_missed",
+				Help: "Whether each scheduled batch job is past the grace period of a scheduled run that has not started.",
+			},
+			[]string{"job"},
+		),
+		Overrunning: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "billing_engine_batch_job_overrunning",
+				Help: "Whether the latest run of each scheduled batch job has been running for longer than its maximum runtime.",
+			},
+			[]string{"job"},
+		),
+		AlertsTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "billing_engine_batch_job_watchdog_alerts_total",
+				Help: "Missed and overrunning batch job runs the watchdog has alerted on, by reason.",
+			},
+			[]string{"job", "reason"},
+		),
+		SkippedTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "billing_engine_batch_job_skipped_total",
+				Help: "Scheduled batch job runs dropped because the previous run was still going.",
+			},
+			[]string{"job"},
+		),
+	}
+
+	AsyncJobs = AsyncJobMetrics{
+		InProgress: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "billing_engine_async_jobs",
+				Help: "Async jobs waiting in the shared queue, or running on this instance.",
+			},
+			[]string{"state"},
+		),
+		RejectedTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "billing_engine_async_jobs_rejected_total",
+				Help: "Total number of async jobs refused because the queue was full.",
+			},
+			[]string{"kind"},
+		),
+		FinishedTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "billing_engine_async_jobs_finished_total",
+				Help: "Total number of async jobs this instance finished, by kind and final status.",
+			},
+			[]string{"kind", "status"},
+		),
+	}
+
+	Collections = CollectionsMetrics{
+		QueueSize: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "billing_engine_collections_queue_size",
+				Help: "Open collection assignments per collector and days-past-due bucket, as of the last assignment run.",
+			},
+			[]string{"collector", "bucket"},
+		),
+		ResolutionTime: promauto.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "billing_engine_collections_resolution_seconds",
+				Help:    "Histogram of the time from assigning a loan to a collector until it was cured or paid off.",
+				Buckets: []float64{86400, 3 * 86400, 7 * 86400, 14 * 86400, 30 * 86400, 60 * 86400, 90 * 86400},
+			},
+			[]string{"resolution"},
+		),
+		ActionsTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "billing_engine_collections_actions_total",
+				Help: "Total number of collection actions recorded.",
+			},
+			[]string{"type"},
+		),
+		EscalatedTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "billing_engine_collections_escalations_total",
+				Help: "Total number of reminders and collection tasks raised by the escalation ladder, by action.",
+			},
+			[]string{"action"},
+		),
+	}
+
+	Integrity = IntegrityMetrics{
+		Violations: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "billing_engine_integrity_violations",
+				Help: "Violations each data integrity check found on its last run.",
+			},
+			[]string{"check"},
+		),
 	}
 )
 
@@ -84,3 +291,118 @@ func RecordLoanCreation() {
 func RecordPayment(status string) {
 	Business.PaymentsProcessedTotal.WithLabelValues(status).Inc()
 }
+
+// RecordPaymentReceived counts a payment that was applied, next to the
+// attempt outcomes RecordPayment counts.
+func RecordPaymentReceived(channel string, amount float64) {
+	Business.PaymentsReceivedTotal.WithLabelValues(channel).Inc()
+	Business.PaymentsReceivedAmount.WithLabelValues(channel).Add(amount)
+}
+
+func RecordDelinquencyChange(isDelinquent bool) {
+	direction := "cured"
+	if isDelinquent {
+		direction = "became_delinquent"
+	}
+	Business.DelinquencyChangesTotal.WithLabelValues(direction).Inc()
+}
+
+func RecordEventPublish(eventType, outcome string) {
+	Messaging.PublishedTotal.WithLabelValues(eventType, outcome).Inc()
+}
+
+func SetEventLogBacklog(unpublished int64) {
+	Messaging.EventLogBacklog.Set(float64(unpublished))
+}
+
+// RecordBatchJob observes one run of job. A run that ended without error
+// also moves the job's last success time to finishedAt, which is what a
+// "job has not succeeded lately" alert compares against.
+func RecordBatchJob(job string, err error, duration time.Duration, finishedAt time.Time) {
+	status := "success"
+	if err != nil {
+		status = "error"
+	}
+	Jobs.Duration.WithLabelValues(job, status).Observe(duration.Seconds())
+	if err == nil {
+		Jobs.LastSuccess.WithLabelValues(job).Set(float64(finishedAt.Unix()))
+	}
+}
+
+// SetBatchJobProgress publishes how far a run of job has got. The counts
+// stay after the run ends, so a scrape after a short run still sees them.
+func SetBatchJobProgress(job string, running bool, processed, total int) {
+	Jobs.Running.WithLabelValues(job).Set(boolValue(running))
+	Jobs.ItemsProcessed.WithLabelValues(job).Set(float64(processed))
+	Jobs.ItemsTotal.WithLabelValues(job).Set(float64(total))
+}
+
+// SetBatchJobWatchdog publishes what the watchdog last found for job.
+func SetBatchJobWatchdog(job string, missed, overrunning bool) {
+	Jobs.Missed.WithLabelValues(job).Set(boolValue(missed))
+	Jobs.Overrunning.WithLabelValues(job).Set(boolValue(overrunning))
+}
+
+// RecordBatchJobAlert counts one watchdog alert on job; reason is "missed"
+// or "overrun".
+func RecordBatchJobAlert(job, reason string) {
+	Jobs.AlertsTotal.WithLabelValues(job, reason).Inc()
+}
+
+func RecordBatchJobSkipped(job string) {
+	Jobs.SkippedTotal.WithLabelValues(job).Inc()
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func SetAsyncJobsQueued(queued int) {
+	AsyncJobs.InProgress.WithLabelValues("queued").Set(float64(queued))
+}
+
+func SetAsyncJobsRunning(running int) {
+	AsyncJobs.InProgress.WithLabelValues("running").Set(float64(running))
+}
+
+func RecordAsyncJobRejected(kind string) {
+	AsyncJobs.RejectedTotal.WithLabelValues(kind).Inc()
+}
+
+func RecordAsyncJobFinished(kind, status string) {
+	AsyncJobs.FinishedTotal.WithLabelValues(kind, status).Inc()
+}
+
+// SetCollectionsQueueSizes replaces the queue size gauges with sizes, keyed
+// by collector and then bucket, so that emptied queues drop to nothing.
+func SetCollectionsQueueSizes(sizes map[string]map[string]int) {
+	Collections.QueueSize.Reset()
+	for collector, buckets := range sizes {
+		for bucket, n := range buckets {
+			Collections.QueueSize.WithLabelValues(collector, bucket).Set(float64(n))
+		}
+	}
+}
+
+func RecordCollectionsResolution(resolution string, duration time.Duration) {
+	Collections.ResolutionTime.WithLabelValues(resolution).Observe(duration.Seconds())
+}
+
+func RecordCollectionsAction(actionType string) {
+	Collections.ActionsTotal.WithLabelValues(actionType).Inc()
+}
+
+func RecordCollectionsEscalation(action string) {
+	Collections.EscalatedTotal.WithLabelValues(action).Inc()
+}
+
+// SetIntegrityViolations sets the violation gauge of every check in counts.
+// A run reports every check, so a check that is clean again drops to zero.
+func SetIntegrityViolations(counts map[string]int) {
+	for check, n := range counts {
+		Integrity.Violations.WithLabelValues(check).Set(float64(n))
+	}
+}
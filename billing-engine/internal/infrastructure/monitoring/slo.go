@@ -0,0 +1,250 @@
+package monitoring
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	DefaultSLOWindow    = time.Hour
+	DefaultSLOObjective = 0.995
+
+	// sloSlots is how many slices a window is kept in. Requests leave the
+	// window one slice at a time, so a summary covers between the window
+	// less one slice and the full window.
+	sloSlots = 60
+)
+
+// sloBuckets are the latency bounds in seconds, shared by the route
+// histogram and the in-process quantiles.
+var sloBuckets = [...]float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type RouteMetrics struct {
+	Duration      *prometheus.HistogramVec
+	RequestsTotal *prometheus.CounterVec
+}
+
+var Routes = RouteMetrics{
+	Duration: promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "billing_engine_http_route_duration_seconds",
+			Help:    "Histogram of request latencies per route, with the trace ID of a sample request as exemplar.",
+			Buckets: sloBuckets[:],
+		},
+		[]string{"method", "route"},
+	),
+	RequestsTotal: promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "billing_engine_http_route_requests_total",
+			Help: "Total number of requests per route by outcome: ok, or error for a 5xx response.",
+		},
+		[]string{"method", "route", "outcome"},
+	),
+}
+
+// RecordRouteRequest adds a request to the route metrics. traceID, when set,
+// is attached as exemplar so a slow bucket or an error leads to a log line.
+func RecordRouteRequest(method, route string, failed bool, duration time.Duration, traceID string) {
+	outcome := "ok"
+	if failed {
+		outcome = "error"
+	}
+	var exemplar prometheus.Labels
+	if traceID != "" {
+		exemplar = prometheus.Labels{"trace_id": traceID}
+	}
+	observer := Routes.Duration.WithLabelValues(method, route)
+	counter := Routes.RequestsTotal.WithLabelValues(method, route, outcome)
+	if exemplar == nil {
+		observer.Observe(duration.Seconds())
+		counter.Inc()
+		return
+	}
+	observer.(prometheus.ExemplarObserver).ObserveWithExemplar(duration.Seconds(), exemplar)
+	counter.(prometheus.ExemplarAdder).AddWithExemplar(1, exemplar)
+}
+
+// SLOTracker keeps per-route request counts and latency buckets over a
+// rolling window, so that the API's health can be read without a metrics
+// backend. Memory is fixed per route: each route holds sloSlots slices of
+// bucket counts rather than individual requests.
+type SLOTracker struct {
+	window    time.Duration
+	slot      time.Duration
+	objective float64
+
+	mu     sync.Mutex
+	routes map[routeKey]*routeWindow
+}
+
+type routeKey struct {
+	method, route string
+}
+
+type routeWindow struct {
+	slots [sloSlots]sloSlot
+}
+
+type sloSlot struct {
+	epoch    int64
+	requests uint64
+	errors   uint64
+	// buckets counts the requests at or below each bound of sloBuckets; the
+	// last entry counts the slower ones.
+	buckets [len(sloBuckets) + 1]uint64
+}
+
+// NewSLOTracker tracks requests over window against objective, the share of
+// requests that must not fail. A window of zero or less means
+// DefaultSLOWindow and an objective outside (0, 1) DefaultSLOObjective.
+func NewSLOTracker(window time.Duration, objective float64) *SLOTracker {
+	if window <= 0 {
+		window = DefaultSLOWindow
+	}
+	if objective <= 0 || objective >= 1 {
+		objective = DefaultSLOObjective
+	}
+	slot := window / sloSlots
+	if slot <= 0 {
+		slot = time.Nanosecond
+	}
+	return &SLOTracker{window: window, slot: slot, objective: objective, routes: map[routeKey]*routeWindow{}}
+}
+
+// Record counts a request that finished at. failed marks a request that
+// spends the error budget.
+func (t *SLOTracker) Record(method, route string, failed bool, duration time.Duration, at time.Time) {
+	epoch := at.UnixNano() / int64(t.slot)
+	bucket := sort.SearchFloat64s(sloBuckets[:], duration.Seconds())
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := routeKey{method, route}
+	w, ok := t.routes[key]
+	if !ok {
+		w = &routeWindow{}
+		t.routes[key] = w
+	}
+	s := &w.slots[epoch%sloSlots]
+	if s.epoch != epoch {
+		*s = sloSlot{epoch: epoch}
+	}
+	s.requests++
+	if failed {
+		s.errors++
+	}
+	s.buckets[bucket]++
+}
+
+// SLOSummary is the state of the window ending at At.
+type SLOSummary struct {
+	At        time.Time
+	Window    time.Duration
+	Objective float64
+	Overall   SLOStats
+	// Routes is sorted by route and then method and leaves out routes
+	// without requests in the window.
+	Routes []RouteSLO
+}
+
+type RouteSLO struct {
+	Method string
+	Route  string
+	SLOStats
+}
+
+// SLOStats describes the requests in the window. BudgetBurnRate is the
+// error rate over the rate the objective allows: at 1 the budget lasts
+// exactly the window, above 1 it runs out early. BudgetRemaining is the
+// share of the window's budget not yet spent and goes negative once it is
+// overspent.
+type SLOStats struct {
+	Requests        uint64
+	Errors          uint64
+	ErrorRate       float64
+	P95             time.Duration
+	P99             time.Duration
+	BudgetBurnRate  float64
+	BudgetRemaining float64
+}
+
+func (t *SLOTracker) Summary(at time.Time) SLOSummary {
+	latest := at.UnixNano() / int64(t.slot)
+
+	t.mu.Lock()
+	var overall sloSlot
+	routes := make([]RouteSLO, 0, len(t.routes))
+	for key, w := range t.routes {
+		var total sloSlot
+		for i := range w.slots {
+			if s := &w.slots[i]; s.epoch > latest-sloSlots && s.epoch <= latest {
+				total.add(s)
+			}
+		}
+		if total.requests == 0 {
+			continue
+		}
+		overall.add(&total)
+		routes = append(routes, RouteSLO{Method: key.method, Route: key.route, SLOStats: t.stats(&total)})
+	}
+	t.mu.Unlock()
+
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Route != routes[j].Route {
+			return routes[i].Route < routes[j].Route
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return SLOSummary{At: at, Window: t.window, Objective: t.objective, Overall: t.stats(&overall), Routes: routes}
+}
+
+func (s *sloSlot) add(o *sloSlot) {
+	s.requests += o.requests
+	s.errors += o.errors
+	for i := range s.buckets {
+		s.buckets[i] += o.buckets[i]
+	}
+}
+
+func (t *SLOTracker) stats(s *sloSlot) SLOStats {
+	st := SLOStats{Requests: s.requests, Errors: s.errors, BudgetRemaining: 1}
+	if s.requests == 0 {
+		return st
+	}
+	st.ErrorRate = float64(s.errors) / float64(s.requests)
+	allowed := 1 - t.objective
+	st.BudgetBurnRate = st.ErrorRate / allowed
+	st.BudgetRemaining = 1 - float64(s.errors)/(allowed*float64(s.requests))
+	st.P95 = quantile(0.95, s)
+	st.P99 = quantile(0.99, s)
+	return st
+}
+
+// quantile interpolates within the bucket the quantile falls into, as
+// Prometheus' histogram_quantile does; above the last bound it reports the
+// last bound.
+func quantile(q float64, s *sloSlot) time.Duration {
+	rank := q * float64(s.requests)
+	var seen float64
+	for i, n := range s.buckets {
+		if n == 0 || seen+float64(n) < rank {
+			seen += float64(n)
+			continue
+		}
+		if i == len(sloBuckets) {
+			break
+		}
+		lower := 0.0
+		if i > 0 {
+			lower = sloBuckets[i-1]
+		}
+		seconds := lower + (sloBuckets[i]-lower)*(rank-seen)/float64(n)
+		return time.Duration(math.Round(seconds * float64(time.Second)))
+	}
+	return time.Duration(sloBuckets[len(sloBuckets)-1] * float64(time.Second))
+}
@@ -0,0 +1,83 @@
+package monitoring
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSLOTracker(t *testing.T) {
+	start := time.Date(2025, 4, 2, 9, 0, 0, 0, time.UTC)
+
+	t.Run("computes quantiles and budget per route", func(t *testing.T) {
+		tracker := NewSLOTracker(time.Hour, 0.99)
+		for i := range 100 {
+			latency := 20 * time.Millisecond
+			if i >= 90 {
+				latency = 300 * time.Millisecond
+			}
+			tracker.Record("GET", "/loans/{loanID}", i < 2, latency, start.Add(time.Duration(i)*time.Second))
+		}
+		tracker.Record("POST", "/loans/", false, 40*time.Millisecond, start)
+
+		summary := tracker.Summary(start.Add(2 * time.Minute))
+
+		require.Len(t, summary.Routes, 2)
+		assert.Equal(t, "/loans/", summary.Routes[0].Route, "routes are sorted")
+		loans := summary.Routes[1]
+		assert.Equal(t, uint64(100), loans.Requests)
+		assert.Equal(t, uint64(2), loans.Errors)
+		assert.InDelta(t, 0.02, loans.ErrorRate, 1e-9)
+		assert.InDelta(t, 2.0, loans.BudgetBurnRate, 1e-9, "twice the allowed error rate")
+		assert.InDelta(t, -1.0, loans.BudgetRemaining, 1e-9, "the budget is spent twice over")
+		// 90 requests are at most 25ms and 10 between 250ms and 500ms.
+		assert.Equal(t, 375*time.Millisecond, loans.P95)
+		assert.Equal(t, 475*time.Millisecond, loans.P99)
+
+		assert.Equal(t, uint64(101), summary.Overall.Requests)
+		assert.Equal(t, time.Hour, summary.Window)
+		assert.Equal(t, 0.99, summary.Objective)
+	})
+
+	t.Run("forgets requests older than the window", func(t *testing.T) {
+		tracker := NewSLOTracker(time.Hour, 0)
+		tracker.Record("GET", "/health", true, time.Millisecond, start)
+		tracker.Record("GET", "/health", false, time.Millisecond, start.Add(30*time.Minute))
+
+		summary := tracker.Summary(start.Add(70 * time.Minute))
+
+		require.Len(t, summary.Routes, 1)
+		assert.Equal(t, uint64(1), summary.Routes[0].Requests)
+		assert.Zero(t, summary.Routes[0].Errors)
+		assert.Equal(t, 1.0, summary.Overall.BudgetRemaining)
+		assert.Equal(t, DefaultSLOObjective, summary.Objective)
+
+		assert.Empty(t, tracker.Summary(start.Add(3*time.Hour)).Routes)
+	})
+
+	t.Run("reports the last bound for requests slower than every bucket", func(t *testing.T) {
+		tracker := NewSLOTracker(time.Hour, 0.99)
+		tracker.Record("GET", "/reports/portfolio", false, time.Minute, start)
+
+		assert.Equal(t, 10*time.Second, tracker.Summary(start).Routes[0].P99)
+	})
+}
+
+func TestRecordRouteRequest(t *testing.T) {
+	Routes.RequestsTotal.Reset()
+
+	RecordRouteRequest("GET", "/loans/{loanID}", false, 10*time.Millisecond, "")
+	RecordRouteRequest("GET", "/loans/{loanID}", true, 10*time.Millisecond, "trace-1")
+
+	expected := `
+		# HELP billing_engine_http_route_requests_total Total number of requests per route by outcome: ok, or error for a 5xx response.
+		# TYPE billing_engine_http_route_requests_total counter
+		billing_engine_http_route_requests_total{method="GET",outcome="error",route="/loans/{loanID}"} 1
+		billing_engine_http_route_requests_total{method="GET",outcome="ok",route="/loans/{loanID}"} 1
+	`
+	assert.NoError(t, testutil.CollectAndCompare(Routes.RequestsTotal, strings.NewReader(expected)))
+}
@@ -0,0 +1,165 @@
+package notify
+
+import (
+	"billing-engine/internal/config"
+	"billing-engine/internal/domain/overview"
+	"billing-engine/internal/pkg/retry"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// tokenLifetime is how long the token minted for a request stays valid;
+// long enough to cover clock skew between the two services.
+const tokenLifetime = time.Minute
+
+// requestRetry keeps a restart of notify-service from failing the page that
+// asked for the log, without holding that page up for long.
+var requestRetry = retry.Policy{MaxAttempts: 3, Initial: 200 * time.Millisecond, Max: time.Second, Jitter: 0.2}
+
+var _ overview.NotificationSource = (*Client)(nil)
+
+// Client reads the delivery log of notify-service through its support API,
+// GET /notifications.
+type Client struct {
+	baseURL *url.URL
+	secret  []byte
+	client  *http.Client
+	retry   retry.Policy
+	now     func() time.Time
+	logger  *slog.Logger
+}
+
+func NewClient(cfg config.NotifyConfig, client *http.Client, logger *slog.Logger) (*Client, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("notify-service URL is empty in configuration")
+	}
+	baseURL, err := url.Parse(strings.TrimSuffix(cfg.URL, "/"))
+	if err != nil || baseURL.Host == "" {
+		return nil, fmt.Errorf("invalid notify-service URL %q", cfg.URL)
+	}
+	if client == nil {
+		client = &http.Client{Timeout: cfg.Timeout}
+	}
+	return &Client{
+		baseURL: baseURL,
+		secret:  []byte(cfg.JWTSecret),
+		client:  client,
+		retry:   requestRetry,
+		now:     time.Now,
+		logger:  logger.With("component", "NotifyClient"),
+	}, nil
+}
+
+// notificationResponse is an entry of notify-service's GET /notifications.
+type notificationResponse struct {
+	ID        int64      `json:"id"`
+	Event     string     `json:"event"`
+	Channel   string     `json:"channel"`
+	Recipient string     `json:"recipient"`
+	Subject   string     `json:"subject"`
+	Status    string     `json:"status"`
+	Error     string     `json:"error"`
+	SentAt    *time.Time `json:"sentAt"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// ListNotifications sends the request again, with a fresh token, after a
+// transport error or an answer that may change on its own: a timeout, rate
+// limiting or a server error.
+func (c *Client) ListNotifications(ctx context.Context, customerID int64, limit int) ([]overview.Notification, error) {
+	u := *c.baseURL
+	u.Path += "/notifications"
+	u.RawQuery = url.Values{
+		"customer_id": {strconv.FormatInt(customerID, 10)},
+		"limit":       {strconv.Itoa(limit)},
+	}.Encode()
+
+	policy := c.retry
+	policy.OnRetry = func(attempt int, err error, wait time.Duration) {
+		c.logger.WarnContext(ctx, "Failed to list notifications, retrying", slog.Int("attempt", attempt), slog.Duration("wait", wait), slog.Any("error", err))
+	}
+	var entries []notificationResponse
+	err := retry.Do(ctx, policy, func(ctx context.Context, _ int) error {
+		var err error
+		entries, err = c.listNotifications(ctx, u.String())
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	notifications := make([]overview.Notification, len(entries))
+	for i, e := range entries {
+		notifications[i] = overview.Notification{
+			ID: e.ID, Event: e.Event, Channel: e.Channel, Recipient: e.Recipient, Subject: e.Subject,
+			Status: e.Status, Error: e.Error, SentAt: e.SentAt, CreatedAt: e.CreatedAt,
+		}
+	}
+	return notifications, nil
+}
+
+// listNotifications makes one attempt, marking the errors another would not
+// fix as permanent.
+func (c *Client) listNotifications(ctx context.Context, rawURL string) ([]notificationResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, retry.Permanent(fmt.Errorf("build notifications request: %w", err))
+	}
+	if len(c.secret) > 0 {
+		token, err := c.token()
+		if err != nil {
+			return nil, retry.Permanent(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		err = fmt.Errorf("list notifications: %w", err)
+		if ctx.Err() != nil {
+			return nil, retry.Permanent(err)
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		err := fmt.Errorf("list notifications: notify-service answered %s", resp.Status)
+		if !retry.RetryableStatus(resp.StatusCode) {
+			return nil, retry.Permanent(err)
+		}
+		return nil, err
+	}
+
+	var entries []notificationResponse
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, retry.Permanent(fmt.Errorf("decode notifications: %w", err))
+	}
+	return entries, nil
+}
+
+// token mints a short-lived staff token. It carries no scope, which
+// notify-service treats as staff.
+func (c *Client) token() (string, error) {
+	now := c.now()
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": "billing-engine",
+		"iat": now.Unix(),
+		"exp": now.Add(tokenLifetime).Unix(),
+	}).SignedString(c.secret)
+	if err != nil {
+		return "", fmt.Errorf("sign notify-service token: %w", err)
+	}
+	return signed, nil
+}
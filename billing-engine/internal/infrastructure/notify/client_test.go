@@ -0,0 +1,125 @@
+package notify
+
+import (
+	"billing-engine/internal/config"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func newTestClient(t *testing.T, secret string, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client, err := NewClient(config.NotifyConfig{URL: server.URL + "/", JWTSecret: secret}, server.Client(), testLogger)
+	require.NoError(t, err)
+	return client
+}
+
+func TestNewClient(t *testing.T) {
+	for _, raw := range []string{"", "notify:8090", "http://"} {
+		_, err := NewClient(config.NotifyConfig{URL: raw}, nil, testLogger)
+		assert.Error(t, err, raw)
+	}
+}
+
+func TestClientListNotifications(t *testing.T) {
+	t.Run("reads the delivery log with a staff token", func(t *testing.T) {
+		var gotPath, gotQuery string
+		var claims jwt.MapClaims
+		client := newTestClient(t, "s3cret", func(w http.ResponseWriter, r *http.Request) {
+			gotPath, gotQuery = r.URL.Path, r.URL.RawQuery
+			token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			parsed, err := jwt.Parse(token, func(*jwt.Token) (any, error) { return []byte("s3cret"), nil })
+			require.NoError(t, err)
+			claims = parsed.Claims.(jwt.MapClaims)
+			_, _ = io.WriteString(w, `[{"id":4,"customerId":7,"event":"payment_reminder","channel":"sms","recipient":"+6281100",
+				"status":"FAILED","error":"unreachable","sentAt":null,"createdAt":"2025-03-03T09:00:00Z"}]`)
+		})
+
+		notifications, err := client.ListNotifications(context.Background(), 7, 10)
+
+		require.NoError(t, err)
+		assert.Equal(t, "/notifications", gotPath)
+		assert.Equal(t, "customer_id=7&limit=10", gotQuery)
+		assert.Equal(t, "billing-engine", claims["sub"])
+		assert.NotContains(t, claims, "scope")
+		require.Len(t, notifications, 1)
+		assert.Equal(t, "payment_reminder", notifications[0].Event)
+		assert.Equal(t, "unreachable", notifications[0].Error)
+		assert.Nil(t, notifications[0].SentAt)
+		assert.Equal(t, time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC), notifications[0].CreatedAt)
+	})
+
+	t.Run("sends no token without a secret", func(t *testing.T) {
+		client := newTestClient(t, "", func(w http.ResponseWriter, r *http.Request) {
+			assert.Empty(t, r.Header.Get("Authorization"))
+			_, _ = io.WriteString(w, `[]`)
+		})
+
+		notifications, err := client.ListNotifications(context.Background(), 7, 10)
+
+		require.NoError(t, err)
+		assert.Empty(t, notifications)
+	})
+
+	t.Run("reports error statuses", func(t *testing.T) {
+		calls := 0
+		client := newTestClient(t, "s3cret", func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			http.Error(w, `{"error":{"message":"Unauthorized"}}`, http.StatusUnauthorized)
+		})
+
+		_, err := client.ListNotifications(context.Background(), 7, 10)
+
+		assert.ErrorContains(t, err, "401")
+		assert.Equal(t, 1, calls, "a client error is not retried")
+	})
+
+	t.Run("retries an unavailable service", func(t *testing.T) {
+		var tokens []string
+		client := newTestClient(t, "s3cret", func(w http.ResponseWriter, r *http.Request) {
+			tokens = append(tokens, r.Header.Get("Authorization"))
+			if len(tokens) < 3 {
+				http.Error(w, "restarting", http.StatusServiceUnavailable)
+				return
+			}
+			_, _ = io.WriteString(w, `[]`)
+		})
+		client.retry.Initial = time.Millisecond
+
+		_, err := client.ListNotifications(context.Background(), 7, 10)
+
+		require.NoError(t, err)
+		assert.Len(t, tokens, 3)
+		for _, token := range tokens {
+			assert.NotEmpty(t, token, "every attempt carries a token")
+		}
+	})
+
+	t.Run("gives up after the last attempt", func(t *testing.T) {
+		calls := 0
+		client := newTestClient(t, "", func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			http.Error(w, "down", http.StatusBadGateway)
+		})
+		client.retry.Initial = time.Millisecond
+
+		_, err := client.ListNotifications(context.Background(), 7, 10)
+
+		assert.ErrorContains(t, err, "502")
+		assert.Equal(t, requestRetry.MaxAttempts, calls)
+	})
+}
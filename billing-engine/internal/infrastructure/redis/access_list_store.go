@@ -0,0 +1,54 @@
+package redis
+
+import (
+	"billing-engine/internal/ratelimit"
+	"context"
+	"fmt"
+)
+
+var _ ratelimit.Store = (*AccessListStore)(nil)
+
+// AccessListStore keeps each rate limit access list in a Redis set, so
+// every instance enforces the same entries.
+type AccessListStore struct {
+	client *Client
+	prefix string
+}
+
+// NewAccessListStore names its sets after prefix, such as
+// "billing-engine:ratelimit:blocklist".
+func NewAccessListStore(client *Client, prefix string) *AccessListStore {
+	return &AccessListStore{client: client, prefix: prefix}
+}
+
+func (s *AccessListStore) Members(ctx context.Context, list ratelimit.List) ([]string, error) {
+	reply, err := s.client.Do(ctx, "SMEMBERS", s.key(list))
+	if err != nil {
+		return nil, err
+	}
+	items, ok := reply.([]any)
+	if !ok {
+		return nil, fmt.Errorf("unexpected SMEMBERS reply %T", reply)
+	}
+	members := make([]string, 0, len(items))
+	for _, item := range items {
+		if member, ok := item.(string); ok {
+			members = append(members, member)
+		}
+	}
+	return members, nil
+}
+
+func (s *AccessListStore) Add(ctx context.Context, list ratelimit.List, principal string) error {
+	_, err := s.client.Do(ctx, "SADD", s.key(list), principal)
+	return err
+}
+
+func (s *AccessListStore) Remove(ctx context.Context, list ratelimit.List, principal string) error {
+	_, err := s.client.Do(ctx, "SREM", s.key(list), principal)
+	return err
+}
+
+func (s *AccessListStore) key(list ratelimit.List) string {
+	return s.prefix + "ratelimit:" + string(list)
+}
@@ -0,0 +1,274 @@
+package redis
+
+import (
+	"billing-engine/internal/config"
+	"billing-engine/internal/ratelimit"
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeServer answers the set commands the access list store sends, the
+// string commands the outstanding cache sends, plus AUTH and SELECT, over
+// real TCP connections. It runs the outstanding cache's scripts in Go
+// rather than Lua, and ignores expiry.
+type fakeServer struct {
+	t        *testing.T
+	listener net.Listener
+	password string
+
+	mu       sync.Mutex
+	sets     map[string]map[string]bool
+	values   map[string]string
+	commands []string
+	conns    []net.Conn
+}
+
+func newFakeServer(t *testing.T, password string) *fakeServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &fakeServer{t: t, listener: listener, password: password, sets: map[string]map[string]bool{}, values: map[string]string{}}
+	t.Cleanup(func() { listener.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *fakeServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns = append(s.conns, conn)
+		s.mu.Unlock()
+		go s.handle(conn)
+	}
+}
+
+// dropConnections closes every open connection, as a server restart would.
+func (s *fakeServer) dropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	s.conns = nil
+}
+
+func (s *fakeServer) handle(conn net.Conn) {
+	defer conn.Close()
+	rd := bufio.NewReader(conn)
+	authed := s.password == ""
+	for {
+		reply, err := readReply(rd)
+		if err != nil {
+			return
+		}
+		var args []string
+		for _, item := range reply.([]any) {
+			args = append(args, item.(string))
+		}
+		s.mu.Lock()
+		s.commands = append(s.commands, args[0])
+		out := s.exec(args, &authed)
+		s.mu.Unlock()
+		if _, err := conn.Write([]byte(out)); err != nil {
+			return
+		}
+	}
+}
+
+func (s *fakeServer) exec(args []string, authed *bool) string {
+	switch {
+	case args[0] == "AUTH":
+		if args[1] != s.password {
+			return "-WRONGPASS invalid password\r\n"
+		}
+		*authed = true
+		return "+OK\r\n"
+	case !*authed:
+		return "-NOAUTH Authentication required.\r\n"
+	case args[0] == "SELECT":
+		return "+OK\r\n"
+	case args[0] == "SADD":
+		if s.sets[args[1]] == nil {
+			s.sets[args[1]] = map[string]bool{}
+		}
+		s.sets[args[1]][args[2]] = true
+		return ":1\r\n"
+	case args[0] == "SREM":
+		delete(s.sets[args[1]], args[2])
+		return ":1\r\n"
+	case args[0] == "SMEMBERS":
+		var b strings.Builder
+		fmt.Fprintf(&b, "*%d\r\n", len(s.sets[args[1]]))
+		for member := range s.sets[args[1]] {
+			fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(member), member)
+		}
+		return b.String()
+	case args[0] == "GET":
+		value, ok := s.values[args[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+	case args[0] == "SET":
+		s.values[args[1]] = args[2]
+		return "+OK\r\n"
+	case args[0] == "DEL":
+		for _, key := range args[1:] {
+			delete(s.values, key)
+		}
+		return fmt.Sprintf(":%d\r\n", len(args)-1)
+	case args[0] == "MGET":
+		var b strings.Builder
+		fmt.Fprintf(&b, "*%d\r\n", len(args)-1)
+		for _, key := range args[1:] {
+			if value, ok := s.values[key]; ok {
+				fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(value), value)
+			} else {
+				b.WriteString("$-1\r\n")
+			}
+		}
+		return b.String()
+	case args[0] == "INCR":
+		return fmt.Sprintf(":%d\r\n", s.incr(args[1]))
+	case args[0] == "EVAL" && args[1] == setOutstandingScript:
+		keys, argv := args[3:6], args[6:]
+		if s.valueOr(keys[1], "0")+":"+s.valueOr(keys[2], "0") != argv[2] {
+			return ":0\r\n"
+		}
+		s.values[keys[0]] = argv[0]
+		return ":1\r\n"
+	case args[0] == "EVAL" && args[1] == deleteOutstandingScript:
+		s.incr(args[4])
+		_, ok := s.values[args[3]]
+		delete(s.values, args[3])
+		if ok {
+			return ":1\r\n"
+		}
+		return ":0\r\n"
+	case args[0] == "SCAN":
+		// Every match comes back in one page.
+		var keys []string
+		for key := range s.values {
+			if ok, _ := path.Match(args[3], key); ok {
+				keys = append(keys, key)
+			}
+		}
+		var b strings.Builder
+		fmt.Fprintf(&b, "*2\r\n$1\r\n0\r\n*%d\r\n", len(keys))
+		for _, key := range keys {
+			fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(key), key)
+		}
+		return b.String()
+	default:
+		return "-ERR unknown command '" + args[0] + "'\r\n"
+	}
+}
+
+func (s *fakeServer) valueOr(key, fallback string) string {
+	if value, ok := s.values[key]; ok {
+		return value
+	}
+	return fallback
+}
+
+func (s *fakeServer) incr(key string) int64 {
+	n, _ := strconv.ParseInt(s.values[key], 10, 64)
+	n++
+	s.values[key] = strconv.FormatInt(n, 10)
+	return n
+}
+
+func (s *fakeServer) seen() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.commands)
+}
+
+func TestNewClient(t *testing.T) {
+	_, err := NewClient(config.RedisConfig{})
+	assert.Error(t, err)
+}
+
+func TestAccessListStore(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeServer(t, "secret")
+	client, err := NewClient(config.RedisConfig{Addr: server.listener.Addr().String(), Password: "secret", DB: 2})
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+	store := NewAccessListStore(client, "billing-engine:")
+
+	require.NoError(t, store.Add(ctx, ratelimit.Blocklist, "203.0.113.9"))
+	require.NoError(t, store.Add(ctx, ratelimit.Blocklist, "198.51.100.4"))
+	require.NoError(t, store.Remove(ctx, ratelimit.Blocklist, "203.0.113.9"))
+
+	members, err := store.Members(ctx, ratelimit.Blocklist)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"198.51.100.4"}, members)
+	assert.True(t, server.sets["billing-engine:ratelimit:blocklist"]["198.51.100.4"])
+	assert.Equal(t, []string{"AUTH", "SELECT", "SADD", "SADD", "SREM", "SMEMBERS"}, server.seen(),
+		"one connection serves every command")
+
+	t.Run("reconnects after the connection drops", func(t *testing.T) {
+		server.dropConnections()
+
+		_, err := store.Members(ctx, ratelimit.Allowlist)
+		if err != nil {
+			// The first command may only find out that the connection is gone.
+			_, err = store.Members(ctx, ratelimit.Allowlist)
+		}
+
+		require.NoError(t, err)
+		assert.Contains(t, server.seen()[6:], "AUTH", "the new connection authenticates again")
+	})
+}
+
+func TestClientErrorReply(t *testing.T) {
+	server := newFakeServer(t, "")
+	client, err := NewClient(config.RedisConfig{Addr: server.listener.Addr().String()})
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	_, err = client.Do(context.Background(), "FLUSHALL")
+	var replyErr Error
+	require.ErrorAs(t, err, &replyErr)
+	assert.Equal(t, "redis: ERR unknown command 'FLUSHALL'", err.Error())
+
+	reply, err := client.Do(context.Background(), "SADD", "k", "v")
+	require.NoError(t, err, "an error reply leaves the connection usable")
+	assert.Equal(t, int64(1), reply)
+}
+
+func TestReadReply(t *testing.T) {
+	for _, tc := range []struct {
+		raw  string
+		want any
+	}{
+		{"+OK\r\n", "OK"},
+		{":42\r\n", int64(42)},
+		{"$5\r\nhello\r\n", "hello"},
+		{"$-1\r\n", nil},
+		{"*2\r\n$1\r\na\r\n:7\r\n", []any{"a", int64(7)}},
+		{"*-1\r\n", nil},
+	} {
+		got, err := readReply(bufio.NewReader(strings.NewReader(tc.raw)))
+		require.NoError(t, err, tc.raw)
+		assert.Equal(t, tc.want, got, tc.raw)
+	}
+
+	_, err := readReply(bufio.NewReader(strings.NewReader("?what\r\n")))
+	assert.Error(t, err)
+}
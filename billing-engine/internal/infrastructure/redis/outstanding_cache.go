@@ -0,0 +1,163 @@
+package redis
+
+import (
+	"billing-engine/internal/domain/loan"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+var _ loan.OutstandingCache = (*OutstandingCache)(nil)
+
+// outstandingVersionTTL keeps a loan's version well past any lookup that
+// could still hold an older one, so the counter starting over at 0 once the
+// loan goes quiet cannot let a stale breakdown through.
+const outstandingVersionTTL = 24 * time.Hour
+
+// setOutstandingScript stores a breakdown only if neither the loan's
+// version nor the epoch moved since the caller read them. KEYS are the
+// breakdown, version and epoch keys; ARGV the breakdown, its TTL in
+// milliseconds and the version read.
+const setOutstandingScript = `
+local version = (redis.call('GET', KEYS[2]) or '0') .. ':' .. (redis.call('GET', KEYS[3]) or '0')
+if version ~= ARGV[3] then
+  return 0
+end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+return 1`
+
+// deleteOutstandingScript moves the loan's version on and drops its
+// breakdown in one step. KEYS are the breakdown and version keys; ARGV the
+// version's TTL in milliseconds.
+const deleteOutstandingScript = `
+redis.call('INCR', KEYS[2])
+redis.call('PEXPIRE', KEYS[2], ARGV[1])
+return redis.call('DEL', KEYS[1])`
+
+// OutstandingCache keeps each loan's outstanding breakdown as JSON under
+// its own key, expiring after the TTL, so a payment on one instance drops
+// the breakdown every instance reads. Next to it a per-loan version counts
+// the loan's deletes and an epoch counts the clears; Set compares both
+// with what the lookup read in a script, so a lookup on one instance that
+// raced with a write on another does not store what it read.
+type OutstandingCache struct {
+	client *Client
+	prefix string
+	ttl    time.Duration
+}
+
+// NewOutstandingCache names its keys after prefix, such as
+// "billing-engine:outstanding:42", "billing-engine:outstanding-version:42"
+// and "billing-engine:outstanding-epoch".
+func NewOutstandingCache(client *Client, prefix string, ttl time.Duration) *OutstandingCache {
+	return &OutstandingCache{client: client, prefix: prefix, ttl: ttl}
+}
+
+func (c *OutstandingCache) Get(ctx context.Context, loanID int64) (*loan.OutstandingBreakdown, bool, error) {
+	reply, err := c.client.Do(ctx, "GET", c.key(loanID))
+	if err != nil || reply == nil {
+		return nil, false, err
+	}
+	value, ok := reply.(string)
+	if !ok {
+		return nil, false, fmt.Errorf("unexpected GET reply %T", reply)
+	}
+	var breakdown loan.OutstandingBreakdown
+	if err := json.Unmarshal([]byte(value), &breakdown); err != nil {
+		return nil, false, fmt.Errorf("decode cached outstanding amount of loan %d: %w", loanID, err)
+	}
+	return &breakdown, true, nil
+}
+
+// Version joins the loan's version and the epoch, either of which is 0
+// until first moved.
+func (c *OutstandingCache) Version(ctx context.Context, loanID int64) (string, error) {
+	reply, err := c.client.Do(ctx, "MGET", c.versionKey(loanID), c.epochKey())
+	if err != nil {
+		return "", err
+	}
+	values, ok := reply.([]any)
+	if !ok || len(values) != 2 {
+		return "", fmt.Errorf("unexpected MGET reply %v", reply)
+	}
+	parts := make([]string, len(values))
+	for i, value := range values {
+		parts[i] = "0"
+		if value, ok := value.(string); ok {
+			parts[i] = value
+		}
+	}
+	return parts[0] + ":" + parts[1], nil
+}
+
+func (c *OutstandingCache) Set(ctx context.Context, breakdown *loan.OutstandingBreakdown, version string) error {
+	value, err := json.Marshal(breakdown)
+	if err != nil {
+		return err
+	}
+	ttl := max(c.ttl.Milliseconds(), 1)
+	_, err = c.client.Do(ctx, "EVAL", setOutstandingScript, "3",
+		c.key(breakdown.LoanID), c.versionKey(breakdown.LoanID), c.epochKey(),
+		string(value), strconv.FormatInt(ttl, 10), version)
+	return err
+}
+
+func (c *OutstandingCache) Delete(ctx context.Context, loanID int64) error {
+	_, err := c.client.Do(ctx, "EVAL", deleteOutstandingScript, "2",
+		c.key(loanID), c.versionKey(loanID), strconv.FormatInt(outstandingVersionTTL.Milliseconds(), 10))
+	return err
+}
+
+// Clear moves the epoch on first, so that lookups already running do not
+// store again what it is about to drop, then walks the keys with SCAN
+// rather than KEYS, which would hold up every other client of the server
+// while it runs.
+func (c *OutstandingCache) Clear(ctx context.Context) error {
+	if _, err := c.client.Do(ctx, "INCR", c.epochKey()); err != nil {
+		return err
+	}
+	cursor := "0"
+	for {
+		reply, err := c.client.Do(ctx, "SCAN", cursor, "MATCH", c.prefix+"outstanding:*", "COUNT", "500")
+		if err != nil {
+			return err
+		}
+		page, ok := reply.([]any)
+		if !ok || len(page) != 2 {
+			return fmt.Errorf("unexpected SCAN reply %v", reply)
+		}
+		next, _ := page[0].(string)
+		keys, _ := page[1].([]any)
+		if len(keys) > 0 {
+			args := []string{"DEL"}
+			for _, key := range keys {
+				if key, ok := key.(string); ok {
+					args = append(args, key)
+				}
+			}
+			if _, err := c.client.Do(ctx, args...); err != nil {
+				return err
+			}
+		}
+		if next == "0" || next == "" {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+func (c *OutstandingCache) key(loanID int64) string {
+	return c.prefix + "outstanding:" + strconv.FormatInt(loanID, 10)
+}
+
+// versionKey and epochKey stay outside the "outstanding:*" pattern Clear
+// deletes.
+func (c *OutstandingCache) versionKey(loanID int64) string {
+	return c.prefix + "outstanding-version:" + strconv.FormatInt(loanID, 10)
+}
+
+func (c *OutstandingCache) epochKey() string {
+	return c.prefix + "outstanding-epoch"
+}
@@ -0,0 +1,76 @@
+package redis
+
+import (
+	"billing-engine/internal/config"
+	"billing-engine/internal/domain/loan"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutstandingCache(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeServer(t, "")
+	client, err := NewClient(config.RedisConfig{Addr: server.listener.Addr().String()})
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+	cache := NewOutstandingCache(client, "billing-engine:", 10*time.Minute)
+
+	_, ok, err := cache.Get(ctx, 42)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	asOf := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	breakdown := &loan.OutstandingBreakdown{LoanID: 42, AsOf: asOf, Fees: 5, Total: 105,
+		Items: []loan.BalanceItem{{Kind: loan.BalanceFee, Amount: 5, Description: "BOUNCE"}}}
+	version, err := cache.Version(ctx, 42)
+	require.NoError(t, err)
+	assert.Equal(t, "0:0", version)
+	require.NoError(t, cache.Set(ctx, breakdown, version))
+	require.NoError(t, cache.Set(ctx, &loan.OutstandingBreakdown{LoanID: 43, Total: 1}, "0:0"))
+	server.mu.Lock()
+	server.values["billing-engine:ratelimit:other"] = "kept"
+	server.mu.Unlock()
+
+	cached, ok, err := cache.Get(ctx, 42)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, breakdown, cached)
+	assert.Contains(t, server.values, "billing-engine:outstanding:42")
+
+	require.NoError(t, cache.Delete(ctx, 42))
+	_, ok, err = cache.Get(ctx, 42)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, cache.Set(ctx, breakdown, version))
+	_, ok, err = cache.Get(ctx, 42)
+	require.NoError(t, err)
+	assert.False(t, ok, "a lookup that read the version before the delete does not store")
+	version, err = cache.Version(ctx, 42)
+	require.NoError(t, err)
+	assert.Equal(t, "1:0", version)
+
+	require.NoError(t, cache.Clear(ctx))
+	assert.NotContains(t, server.values, "billing-engine:outstanding:43")
+	assert.Equal(t, "kept", server.values["billing-engine:ratelimit:other"])
+	require.NoError(t, cache.Set(ctx, breakdown, version))
+	_, ok, err = cache.Get(ctx, 42)
+	require.NoError(t, err)
+	assert.False(t, ok, "a lookup that read the version before the clear does not store")
+	version, err = cache.Version(ctx, 42)
+	require.NoError(t, err)
+	require.NoError(t, cache.Set(ctx, breakdown, version))
+	_, ok, err = cache.Get(ctx, 42)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	server.mu.Lock()
+	server.values["billing-engine:outstanding:44"] = "{"
+	server.mu.Unlock()
+	_, _, err = cache.Get(ctx, 44)
+	assert.Error(t, err)
+}
@@ -0,0 +1,342 @@
+package storage
+
+import (
+	"billing-engine/internal/config"
+	"billing-engine/internal/domain/document"
+	"billing-engine/internal/domain/note"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	signingAlgorithm = "AWS4-HMAC-SHA256"
+	signingService   = "s3"
+	unsignedPayload  = "UNSIGNED-PAYLOAD"
+	amzDateFormat    = "20060102T150405Z"
+	// emptyPayloadHash is the SHA-256 of an empty body.
+	emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	// maxPresignExpiry is the longest a Signature Version 4 link can be
+	// valid for.
+	maxPresignExpiry = 7 * 24 * time.Hour
+)
+
+var (
+	_ note.ObjectStore     = (*S3Store)(nil)
+	_ document.ObjectStore = (*S3Store)(nil)
+)
+
+// S3Store talks to any S3-compatible service (AWS S3, MinIO, Ceph) using
+// path-style URLs and Signature Version 4, so it works without an SDK and
+// without per-bucket DNS.
+type S3Store struct {
+	endpoint  *url.URL
+	region    string
+	bucket    string
+	accessKey string
+	secretKey string
+	client    *http.Client
+	now       func() time.Time
+	logger    *slog.Logger
+}
+
+func NewS3Store(cfg config.StorageConfig, client *http.Client, logger *slog.Logger) (*S3Store, error) {
+	if cfg.Endpoint == "" {
+		return nil, fmt.Errorf("storage endpoint is empty in configuration")
+	}
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("storage bucket is empty in configuration")
+	}
+	endpoint, err := url.Parse(strings.TrimSuffix(cfg.Endpoint, "/"))
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid storage endpoint %q", cfg.Endpoint)
+	}
+	region := cfg.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	if client == nil {
+		client = &http.Client{Timeout: cfg.Timeout}
+	}
+	return &S3Store{
+		endpoint:  endpoint,
+		region:    region,
+		bucket:    cfg.Bucket,
+		accessKey: cfg.AccessKeyID,
+		secretKey: cfg.SecretAccessKey,
+		client:    client,
+		now:       time.Now,
+		logger:    logger.With("component", "S3Store"),
+	}, nil
+}
+
+// Put streams the body without hashing it first; the request is signed with
+// UNSIGNED-PAYLOAD and the exact Content-Length.
+func (s *S3Store) Put(ctx context.Context, key, contentType string, body io.Reader, size int64) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(key), body)
+	if err != nil {
+		return fmt.Errorf("build put request: %w", err)
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", contentType)
+	s.sign(req, unsignedPayload)
+
+	return s.do(req, key, http.StatusOK)
+}
+
+// Get returns the object's body for the caller to close, or ErrNotFound
+// when nothing is stored under key.
+func (s *S3Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.objectURL(key), nil)
+	if err != nil {
+		return nil, fmt.Errorf("build get request: %w", err)
+	}
+	s.sign(req, emptyPayloadHash)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s object %s: %w", req.Method, key, err)
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: object %s", apperrors.ErrNotFound, key)
+	}
+	defer resp.Body.Close()
+	return nil, s.statusError(req, key, resp)
+}
+
+// Size sends a HEAD request and returns the object's Content-Length, or
+// ErrNotFound when nothing is stored under key.
+func (s *S3Store) Size(ctx context.Context, key string) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, s.objectURL(key), nil)
+	if err != nil {
+		return 0, fmt.Errorf("build head request: %w", err)
+	}
+	s.sign(req, emptyPayloadHash)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("%s object %s: %w", req.Method, key, err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.ContentLength, nil
+	case http.StatusNotFound:
+		return 0, fmt.Errorf("%w: object %s", apperrors.ErrNotFound, key)
+	}
+	return 0, s.statusError(req, key, resp)
+}
+
+// PresignGet signs a GET of the object into the URL's query string, so that
+// whoever holds the link can download the object without credentials until
+// expires has passed. Signature Version 4 caps expires at seven days.
+func (s *S3Store) PresignGet(key string, expires time.Duration) (string, error) {
+	return s.presign(http.MethodGet, key, nil, expires)
+}
+
+// PresignPut signs a PUT of the object the same way. Content-Type is signed
+// with the host, so the upload must carry contentType or the store refuses
+// it.
+func (s *S3Store) PresignPut(key, contentType string, expires time.Duration) (string, error) {
+	return s.presign(http.MethodPut, key, map[string]string{"content-type": contentType}, expires)
+}
+
+// presign signs method into the query string of the object's URL. headers,
+// keyed in lower case, are signed next to host and must be sent as given.
+func (s *S3Store) presign(method, key string, headers map[string]string, expires time.Duration) (string, error) {
+	if expires < time.Second || expires > maxPresignExpiry {
+		return "", fmt.Errorf("link expiry must be between 1s and %s, got %s", maxPresignExpiry, expires)
+	}
+	u, err := url.Parse(s.objectURL(key))
+	if err != nil {
+		return "", fmt.Errorf("build object URL: %w", err)
+	}
+	now := s.now().UTC()
+	amzDate := now.Format(amzDateFormat)
+	credentialScope := strings.Join([]string{amzDate[:8], s.region, signingService, "aws4_request"}, "/")
+
+	signed := map[string]string{"host": u.Host}
+	for name, value := range headers {
+		signed[name] = value
+	}
+	headerNames := make([]string, 0, len(signed))
+	for name := range signed {
+		headerNames = append(headerNames, name)
+	}
+	sort.Strings(headerNames)
+	var canonicalHeaders strings.Builder
+	for _, name := range headerNames {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(signed[name]) + "\n")
+	}
+	signedHeaders := strings.Join(headerNames, ";")
+
+	params := map[string]string{
+		"X-Amz-Algorithm":     signingAlgorithm,
+		"X-Amz-Credential":    s.accessKey + "/" + credentialScope,
+		"X-Amz-Date":          amzDate,
+		"X-Amz-Expires":       strconv.Itoa(int(expires / time.Second)),
+		"X-Amz-SignedHeaders": signedHeaders,
+	}
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	query := make([]string, len(names))
+	for i, name := range names {
+		query[i] = uriEncode(name) + "=" + uriEncode(params[name])
+	}
+	canonicalQuery := strings.Join(query, "&")
+
+	canonicalRequest := strings.Join([]string{
+		method,
+		u.EscapedPath(),
+		canonicalQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		unsignedPayload,
+	}, "\n")
+	stringToSign := strings.Join([]string{
+		signingAlgorithm,
+		amzDate,
+		credentialScope,
+		hashHex([]byte(canonicalRequest)),
+	}, "\n")
+	signature := hex.EncodeToString(hmacSHA256(signingKey(s.secretKey, amzDate[:8], s.region, signingService), stringToSign))
+
+	u.RawQuery = canonicalQuery + "&X-Amz-Signature=" + signature
+	return u.String(), nil
+}
+
+// Delete succeeds when the object is already gone.
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, s.objectURL(key), nil)
+	if err != nil {
+		return fmt.Errorf("build delete request: %w", err)
+	}
+	s.sign(req, emptyPayloadHash)
+
+	return s.do(req, key, http.StatusNoContent, http.StatusOK, http.StatusNotFound)
+}
+
+func (s *S3Store) do(req *http.Request, key string, okStatus ...int) error {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s object %s: %w", req.Method, key, err)
+	}
+	defer resp.Body.Close()
+
+	for _, status := range okStatus {
+		if resp.StatusCode == status {
+			io.Copy(io.Discard, resp.Body)
+			return nil
+		}
+	}
+
+	return s.statusError(req, key, resp)
+}
+
+func (s *S3Store) statusError(req *http.Request, key string, resp *http.Response) error {
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	s.logger.Error("Object storage request failed", "method", req.Method, "key", key, "status", resp.StatusCode, "response", string(detail))
+	return fmt.Errorf("%s object %s: unexpected status %d", req.Method, key, resp.StatusCode)
+}
+
+func (s *S3Store) objectURL(key string) string {
+	u := *s.endpoint
+	u.Path = s.endpoint.Path + "/" + s.bucket + "/" + key
+	u.RawPath = s.endpoint.EscapedPath() + "/" + uriEncode(s.bucket) + "/" + encodeKey(key)
+	return u.String()
+}
+
+// sign adds the Signature Version 4 headers. Only host and the x-amz headers
+// are signed, which keeps proxies that rewrite other headers from breaking
+// the signature.
+func (s *S3Store) sign(req *http.Request, payloadHash string) {
+	now := s.now().UTC()
+	amzDate := now.Format(amzDateFormat)
+	date := amzDate[:8]
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		"host:" + req.URL.Host + "\n" +
+			"x-amz-content-sha256:" + payloadHash + "\n" +
+			"x-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	credentialScope := strings.Join([]string{date, s.region, signingService, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		signingAlgorithm,
+		amzDate,
+		credentialScope,
+		hashHex([]byte(canonicalRequest)),
+	}, "\n")
+
+	signature := hex.EncodeToString(hmacSHA256(signingKey(s.secretKey, date, s.region, signingService), stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		signingAlgorithm, s.accessKey, credentialScope, signedHeaders, signature))
+}
+
+func signingKey(secret, date, region, service string) []byte {
+	key := hmacSHA256([]byte("AWS4"+secret), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	return hmacSHA256(key, "aws4_request")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func encodeKey(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = uriEncode(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// uriEncode escapes everything but the RFC 3986 unreserved characters, as
+// Signature Version 4 requires. url.PathEscape leaves sub-delimiters alone.
+func uriEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
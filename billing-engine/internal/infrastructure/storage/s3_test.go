@@ -0,0 +1,183 @@
+package storage
+
+import (
+	"billing-engine/internal/config"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func newTestStore(t *testing.T, handler http.HandlerFunc) *S3Store {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	store, err := NewS3Store(config.StorageConfig{
+		Endpoint:        server.URL,
+		Bucket:          "attachments",
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "secret",
+	}, server.Client(), testLogger)
+	require.NoError(t, err)
+	store.now = func() time.Time { return time.Date(2025, 3, 14, 9, 26, 53, 0, time.UTC) }
+	return store
+}
+
+func TestSigningKey(t *testing.T) {
+	// Example from the AWS Signature Version 4 documentation.
+	key := signingKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20150830", "us-east-1", "iam")
+	assert.Equal(t, "c4afb1cc5771d871763a393e44b703571b55cc28424d1a5e86da6ed3c154a4b9", hex.EncodeToString(key))
+}
+
+func TestS3StorePut(t *testing.T) {
+	var gotPath, gotAuth, gotType, gotBody string
+	var gotLength int64
+	store := newTestStore(t, func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.EscapedPath()
+		gotAuth = r.Header.Get("Authorization")
+		gotType = r.Header.Get("Content-Type")
+		gotLength = r.ContentLength
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	err := store.Put(context.Background(), "loans/42/contract (signed).pdf", "application/pdf", strings.NewReader("%PDF"), 4)
+
+	require.NoError(t, err)
+	assert.Equal(t, "/attachments/loans/42/contract%20%28signed%29.pdf", gotPath)
+	assert.Equal(t, "application/pdf", gotType)
+	assert.Equal(t, int64(4), gotLength)
+	assert.Equal(t, "%PDF", gotBody)
+	assert.True(t, strings.HasPrefix(gotAuth,
+		"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20250314/us-east-1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature="),
+		"unexpected Authorization header %q", gotAuth)
+}
+
+func TestS3StorePutRejected(t *testing.T) {
+	store := newTestStore(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		io.WriteString(w, "<Error><Code>SignatureDoesNotMatch</Code></Error>")
+	})
+
+	err := store.Put(context.Background(), "loans/42/k", "text/plain", strings.NewReader("x"), 1)
+
+	assert.ErrorContains(t, err, "unexpected status 403")
+}
+
+func TestS3StoreDelete(t *testing.T) {
+	var gotMethod, gotHash string
+	store := newTestStore(t, func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+		gotHash = r.Header.Get("X-Amz-Content-Sha256")
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	err := store.Delete(context.Background(), "loans/42/k")
+
+	require.NoError(t, err)
+	assert.Equal(t, http.MethodDelete, gotMethod)
+	assert.Equal(t, emptyPayloadHash, gotHash)
+}
+
+func TestS3StoreGet(t *testing.T) {
+	store := newTestStore(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/attachments/loans/42/certificate.pdf" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		assert.Equal(t, http.MethodGet, r.Method)
+		assert.Equal(t, emptyPayloadHash, r.Header.Get("X-Amz-Content-Sha256"))
+		io.WriteString(w, "%PDF-1.4")
+	})
+
+	body, err := store.Get(context.Background(), "loans/42/certificate.pdf")
+	require.NoError(t, err)
+	content, _ := io.ReadAll(body)
+	body.Close()
+	assert.Equal(t, "%PDF-1.4", string(content))
+
+	_, err = store.Get(context.Background(), "loans/42/missing.pdf")
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+}
+
+func TestS3StorePresignGet(t *testing.T) {
+	store := newTestStore(t, func(w http.ResponseWriter, r *http.Request) {})
+
+	link, err := store.PresignGet("loans/42/payoff certificate.pdf", 24*time.Hour)
+	require.NoError(t, err)
+
+	u, err := url.Parse(link)
+	require.NoError(t, err)
+	assert.Equal(t, "/attachments/loans/42/payoff%20certificate.pdf", u.EscapedPath())
+	query := u.Query()
+	assert.Equal(t, "AWS4-HMAC-SHA256", query.Get("X-Amz-Algorithm"))
+	assert.Equal(t, "AKIDEXAMPLE/20250314/us-east-1/s3/aws4_request", query.Get("X-Amz-Credential"))
+	assert.Equal(t, "20250314T092653Z", query.Get("X-Amz-Date"))
+	assert.Equal(t, "86400", query.Get("X-Amz-Expires"))
+	assert.Equal(t, "host", query.Get("X-Amz-SignedHeaders"))
+	assert.Len(t, query.Get("X-Amz-Signature"), 64)
+	assert.True(t, strings.HasSuffix(u.RawQuery, "&X-Amz-Signature="+query.Get("X-Amz-Signature")), "the signature comes last")
+
+	again, err := store.PresignGet("loans/42/payoff certificate.pdf", 24*time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, link, again, "the same request at the same time signs the same")
+
+	_, err = store.PresignGet("loans/42/k", 8*24*time.Hour)
+	assert.ErrorContains(t, err, "link expiry")
+}
+
+func TestS3StorePresignPut(t *testing.T) {
+	store := newTestStore(t, func(w http.ResponseWriter, r *http.Request) {})
+
+	link, err := store.PresignPut("customers/7/documents/id.png", "image/png", 15*time.Minute)
+	require.NoError(t, err)
+
+	u, err := url.Parse(link)
+	require.NoError(t, err)
+	assert.Equal(t, "900", u.Query().Get("X-Amz-Expires"))
+	assert.Equal(t, "content-type;host", u.Query().Get("X-Amz-SignedHeaders"), "the upload must carry the content type it was signed for")
+
+	get, err := store.PresignGet("customers/7/documents/id.png", 15*time.Minute)
+	require.NoError(t, err)
+	getURL, err := url.Parse(get)
+	require.NoError(t, err)
+	assert.NotEqual(t, u.Query().Get("X-Amz-Signature"), getURL.Query().Get("X-Amz-Signature"), "the method is signed")
+}
+
+func TestS3StoreSize(t *testing.T) {
+	store := newTestStore(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodHead, r.Method)
+		if r.URL.Path != "/attachments/customers/7/documents/id.png" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Length", "2048")
+		w.WriteHeader(http.StatusOK)
+	})
+
+	size, err := store.Size(context.Background(), "customers/7/documents/id.png")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2048), size)
+
+	_, err = store.Size(context.Background(), "customers/7/documents/missing.png")
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+}
+
+func TestNewS3StoreRequiresBucket(t *testing.T) {
+	_, err := NewS3Store(config.StorageConfig{Endpoint: "http://localhost:9000"}, nil, testLogger)
+	assert.ErrorContains(t, err, "bucket")
+}
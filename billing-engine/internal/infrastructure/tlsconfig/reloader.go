@@ -0,0 +1,195 @@
+package tlsconfig
+
+import (
+	"billing-engine/internal/config"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ClientAuthNone     = "none"
+	ClientAuthOptional = "optional"
+	ClientAuthRequire  = "require"
+
+	defaultReloadInterval = time.Minute
+)
+
+// Reloader serves the certificate, key and client CA bundle named in the
+// config and picks up new versions of those files while the server runs.
+// Changes are detected by modification time and size, checked at most once
+// per reload interval during a handshake. A file that fails to load is
+// logged and the previous material keeps being served.
+type Reloader struct {
+	certFile       string
+	keyFile        string
+	clientCAFile   string
+	clientAuth     tls.ClientAuthType
+	reloadInterval time.Duration
+	logger         *slog.Logger
+	now            func() time.Time
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	versions  map[string]fileVersion
+	checkedAt time.Time
+}
+
+type fileVersion struct {
+	modTime time.Time
+	size    int64
+}
+
+func NewReloader(cfg config.TLSConfig, logger *slog.Logger) (*Reloader, error) {
+	if !cfg.Enabled() {
+		return nil, fmt.Errorf("TLS certificate and key files must both be configured")
+	}
+	clientAuth, err := parseClientAuth(cfg.ClientAuth, cfg.ClientCAFile != "")
+	if err != nil {
+		return nil, err
+	}
+	interval := cfg.ReloadInterval
+	if interval <= 0 {
+		interval = defaultReloadInterval
+	}
+
+	r := &Reloader{
+		certFile:       cfg.CertFile,
+		keyFile:        cfg.KeyFile,
+		clientCAFile:   cfg.ClientCAFile,
+		clientAuth:     clientAuth,
+		reloadInterval: interval,
+		logger:         logger.With("component", "TLSReloader"),
+		now:            time.Now,
+	}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	r.checkedAt = r.now()
+	return r, nil
+}
+
+func parseClientAuth(mode string, hasCA bool) (tls.ClientAuthType, error) {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "":
+		if hasCA {
+			return tls.RequireAndVerifyClientCert, nil
+		}
+		return tls.NoClientCert, nil
+	case ClientAuthNone:
+		return tls.NoClientCert, nil
+	case ClientAuthOptional:
+		if !hasCA {
+			return 0, fmt.Errorf("TLS client auth %q needs a client CA file", mode)
+		}
+		return tls.VerifyClientCertIfGiven, nil
+	case ClientAuthRequire:
+		if !hasCA {
+			return 0, fmt.Errorf("TLS client auth %q needs a client CA file", mode)
+		}
+		return tls.RequireAndVerifyClientCert, nil
+	}
+	return 0, fmt.Errorf("unknown TLS client auth %q, use %q, %q or %q", mode, ClientAuthNone, ClientAuthOptional, ClientAuthRequire)
+}
+
+// TLSConfig returns the server config. Every handshake goes through
+// GetConfigForClient, so connections opened after a reload use the new
+// files while established connections are left alone.
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: []string{"h2", "http/1.1"},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cert, clientCAs := r.current()
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				NextProtos:   []string{"h2", "http/1.1"},
+				Certificates: []tls.Certificate{*cert},
+				ClientAuth:   r.clientAuth,
+				ClientCAs:    clientCAs,
+			}, nil
+		},
+	}
+}
+
+func (r *Reloader) current() (*tls.Certificate, *x509.CertPool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if now := r.now(); now.Sub(r.checkedAt) >= r.reloadInterval {
+		r.checkedAt = now
+		if r.changed() {
+			if err := r.load(); err != nil {
+				r.logger.Error("Failed to reload TLS files, keeping the current certificate", slog.Any("error", err))
+			} else {
+				r.logger.Info("Reloaded TLS certificate", slog.String("certFile", r.certFile))
+			}
+		}
+	}
+	return r.cert, r.clientCAs
+}
+
+// changed must be called with r.mu held.
+func (r *Reloader) changed() bool {
+	for _, name := range r.files() {
+		info, err := os.Stat(name)
+		if err != nil {
+			// Let load report the missing file.
+			return true
+		}
+		if r.versions[name] != (fileVersion{modTime: info.ModTime(), size: info.Size()}) {
+			return true
+		}
+	}
+	return false
+}
+
+// load must be called with r.mu held, or before r is shared. Versions are
+// only recorded after everything parsed, so a half-written rotation is
+// retried on the next check.
+func (r *Reloader) load() error {
+	versions := make(map[string]fileVersion, 3)
+	for _, name := range r.files() {
+		info, err := os.Stat(name)
+		if err != nil {
+			return fmt.Errorf("stat TLS file: %w", err)
+		}
+		versions[name] = fileVersion{modTime: info.ModTime(), size: info.Size()}
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("load TLS key pair: %w", err)
+	}
+
+	var clientCAs *x509.CertPool
+	if r.clientCAFile != "" {
+		pem, err := os.ReadFile(r.clientCAFile)
+		if err != nil {
+			return fmt.Errorf("read client CA file: %w", err)
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("client CA file %s contains no PEM certificates", r.clientCAFile)
+		}
+	}
+
+	r.cert = &cert
+	r.clientCAs = clientCAs
+	r.versions = versions
+	return nil
+}
+
+func (r *Reloader) files() []string {
+	files := []string{r.certFile, r.keyFile}
+	if r.clientCAFile != "" {
+		files = append(files, r.clientCAFile)
+	}
+	return files
+}
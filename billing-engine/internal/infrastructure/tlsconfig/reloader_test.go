@@ -0,0 +1,238 @@
+package tlsconfig
+
+import (
+	"billing-engine/internal/config"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"log/slog"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a PEM certificate and key for a leaf signed by ca.
+func (ca *testCA) issue(t *testing.T, serial int64, name string, usage x509.ExtKeyUsage) ([]byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeFile(t *testing.T, path string, data []byte, modTime time.Time) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+// handshake connects a client that trusts ca, optionally presenting
+// clientCert, and returns the serial of the server certificate it saw.
+func handshake(t *testing.T, serverConfig *tls.Config, ca *testCA, clientCert *tls.Certificate) (int64, error) {
+	t.Helper()
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", serverConfig)
+	require.NoError(t, err)
+	defer ln.Close()
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_ = conn.(*tls.Conn).Handshake()
+		_, _ = conn.Read(make([]byte, 1))
+	}()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	clientConfig := &tls.Config{RootCAs: roots, ServerName: "billing.local", MinVersion: tls.VersionTLS12}
+	if clientCert != nil {
+		// Present the certificate even when the server does not list its CA,
+		// so verification on the server side is what gets tested.
+		clientConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return clientCert, nil
+		}
+	}
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: 5 * time.Second}, "tcp", ln.Addr().String(), clientConfig)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	// With TLS 1.3 a rejected client certificate only surfaces on the first
+	// read after the handshake.
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Write([]byte("x")); err != nil {
+		return 0, err
+	}
+	if _, err := conn.Read(make([]byte, 1)); err != nil && !errors.Is(err, io.EOF) {
+		return 0, err
+	}
+	return conn.ConnectionState().PeerCertificates[0].SerialNumber.Int64(), nil
+}
+
+func TestNewReloaderValidation(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, "test-ca")
+	certPEM, keyPEM := ca.issue(t, 2, "billing.local", x509.ExtKeyUsageServerAuth)
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	writeFile(t, certFile, certPEM, time.Now())
+	writeFile(t, keyFile, keyPEM, time.Now())
+
+	tests := []struct {
+		name    string
+		cfg     config.TLSConfig
+		wantErr string
+	}{
+		{"missing key file setting", config.TLSConfig{CertFile: certFile}, "must both be configured"},
+		{"unreadable key pair", config.TLSConfig{CertFile: certFile, KeyFile: filepath.Join(dir, "missing.key")}, "stat TLS file"},
+		{"client auth without CA", config.TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientAuth: ClientAuthRequire}, "needs a client CA file"},
+		{"unknown client auth", config.TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientAuth: "sometimes"}, "unknown TLS client auth"},
+		{"CA file without certificates", config.TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: keyFile}, "contains no PEM certificates"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewReloader(tt.cfg, testLogger)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestReloaderMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, "test-ca")
+	certPEM, keyPEM := ca.issue(t, 2, "billing.local", x509.ExtKeyUsageServerAuth)
+	certFile, keyFile, caFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), filepath.Join(dir, "ca.crt")
+	writeFile(t, certFile, certPEM, time.Now())
+	writeFile(t, keyFile, keyPEM, time.Now())
+	writeFile(t, caFile, ca.pem, time.Now())
+
+	clientPEM, clientKeyPEM := ca.issue(t, 3, "notify-service", x509.ExtKeyUsageClientAuth)
+	trusted, err := tls.X509KeyPair(clientPEM, clientKeyPEM)
+	require.NoError(t, err)
+	otherPEM, otherKeyPEM := newTestCA(t, "other-ca").issue(t, 4, "intruder", x509.ExtKeyUsageClientAuth)
+	untrusted, err := tls.X509KeyPair(otherPEM, otherKeyPEM)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		clientAuth string
+		clientCert *tls.Certificate
+		wantErr    bool
+	}{
+		{"required and presented", "", &trusted, false},
+		{"required but missing", ClientAuthRequire, nil, true},
+		{"required but signed by another CA", ClientAuthRequire, &untrusted, true},
+		{"optional and missing", ClientAuthOptional, nil, false},
+		{"optional but signed by another CA", ClientAuthOptional, &untrusted, true},
+		{"disabled", ClientAuthNone, &untrusted, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewReloader(config.TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile, ClientAuth: tt.clientAuth}, testLogger)
+			require.NoError(t, err)
+
+			serial, err := handshake(t, r.TLSConfig(), ca, tt.clientCert)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, int64(2), serial)
+		})
+	}
+}
+
+func TestReloaderPicksUpRotatedCertificate(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, "test-ca")
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	written := time.Now().Add(-time.Hour)
+	certPEM, keyPEM := ca.issue(t, 2, "billing.local", x509.ExtKeyUsageServerAuth)
+	writeFile(t, certFile, certPEM, written)
+	writeFile(t, keyFile, keyPEM, written)
+
+	r, err := NewReloader(config.TLSConfig{CertFile: certFile, KeyFile: keyFile, ReloadInterval: time.Minute}, testLogger)
+	require.NoError(t, err)
+	now := time.Now()
+	r.now = func() time.Time { return now }
+
+	serial, err := handshake(t, r.TLSConfig(), ca, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), serial)
+
+	rotatedPEM, rotatedKeyPEM := ca.issue(t, 5, "billing.local", x509.ExtKeyUsageServerAuth)
+	writeFile(t, certFile, rotatedPEM, written.Add(time.Minute))
+	writeFile(t, keyFile, rotatedKeyPEM, written.Add(time.Minute))
+
+	serial, err = handshake(t, r.TLSConfig(), ca, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), serial, "files should not be checked again before the reload interval")
+
+	now = now.Add(time.Minute)
+	serial, err = handshake(t, r.TLSConfig(), ca, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), serial)
+
+	// A broken rotation keeps the last good certificate.
+	writeFile(t, keyFile, []byte("not a key"), written.Add(2*time.Minute))
+	now = now.Add(time.Minute)
+	serial, err = handshake(t, r.TLSConfig(), ca, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), serial)
+}
@@ -0,0 +1,148 @@
+package topology
+
+import (
+	"billing-engine/internal/config"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// recordingDeclarer writes every call down and fails the ones named in fail.
+type recordingDeclarer struct {
+	calls []string
+	args  map[string]amqp.Table
+	fail  string
+}
+
+func (d *recordingDeclarer) record(call string) error {
+	d.calls = append(d.calls, call)
+	if call == d.fail {
+		return errors.New("PRECONDITION_FAILED")
+	}
+	return nil
+}
+
+func (d *recordingDeclarer) ExchangeDeclare(name, kind string, durable, _, _, _ bool, _ amqp.Table) error {
+	return d.record(fmt.Sprintf("exchange %s %s durable=%t", name, kind, durable))
+}
+
+func (d *recordingDeclarer) QueueDeclare(name string, durable, _, _, _ bool, args amqp.Table) (amqp.Queue, error) {
+	if d.args == nil {
+		d.args = map[string]amqp.Table{}
+	}
+	d.args[name] = args
+	return amqp.Queue{Name: name}, d.record(fmt.Sprintf("queue %s durable=%t", name, durable))
+}
+
+func (d *recordingDeclarer) QueueBind(name, key, exchange string, _ bool, _ amqp.Table) error {
+	return d.record(fmt.Sprintf("bind %s %s %s", name, exchange, key))
+}
+
+func TestApplyDefaultTopology(t *testing.T) {
+	d := &recordingDeclarer{}
+
+	require.NoError(t, Apply(d, config.DefaultTopology("billing-engine"), testLogger))
+
+	assert.Equal(t, []string{
+		"exchange billing-engine topic durable=true",
+		"exchange billing-engine.dlx topic durable=true",
+		"queue notify-service.customer durable=true",
+		"bind notify-service.customer billing-engine customer.created",
+		"bind notify-service.customer billing-engine customer.updated",
+		"bind notify-service.customer billing-engine customer.delinquency.changed",
+		"bind notify-service.customer billing-engine customer.preferences.changed",
+		"bind notify-service.customer billing-engine customer.contacts.changed",
+		"bind notify-service.customer billing-engine customer.contact.verification_requested",
+		"queue notify-service.loan durable=true",
+		"bind notify-service.loan billing-engine loan.created",
+		"bind notify-service.loan billing-engine loan.payment.received",
+		"bind notify-service.loan billing-engine loan.delinquent",
+		"bind notify-service.loan billing-engine loan.paid_off",
+		"bind notify-service.loan billing-engine loan.reminder.due",
+		"queue notify-service.customer.dlq durable=true",
+		"bind notify-service.customer.dlq billing-engine.dlx customer.created",
+		"bind notify-service.customer.dlq billing-engine.dlx customer.updated",
+		"bind notify-service.customer.dlq billing-engine.dlx customer.delinquency.changed",
+		"bind notify-service.customer.dlq billing-engine.dlx customer.preferences.changed",
+		"bind notify-service.customer.dlq billing-engine.dlx customer.contacts.changed",
+		"bind notify-service.customer.dlq billing-engine.dlx customer.contact.verification_requested",
+		"queue notify-service.loan.dlq durable=true",
+		"bind notify-service.loan.dlq billing-engine.dlx loan.created",
+		"bind notify-service.loan.dlq billing-engine.dlx loan.payment.received",
+		"bind notify-service.loan.dlq billing-engine.dlx loan.delinquent",
+		"bind notify-service.loan.dlq billing-engine.dlx loan.paid_off",
+		"bind notify-service.loan.dlq billing-engine.dlx loan.reminder.due",
+	}, d.calls)
+	assert.Equal(t, amqp.Table{"x-dead-letter-exchange": "billing-engine.dlx"}, d.args["notify-service.customer"])
+	assert.Nil(t, d.args["notify-service.customer.dlq"])
+}
+
+func TestApplyRetryQueue(t *testing.T) {
+	d := &recordingDeclarer{}
+	cfg := config.TopologyConfig{
+		Exchanges: []config.ExchangeConfig{{Name: "billing-engine"}, {Name: "billing-engine.retry", Type: amqp.ExchangeDirect}},
+		Queues: []config.QueueConfig{{
+			Name: "notify-service.retry", DeadLetterExchange: "billing-engine", DeadLetterRoutingKey: "customer.updated", MessageTTL: 30 * time.Second,
+			Bindings: []config.BindingConfig{{Exchange: "billing-engine.retry", RoutingKeys: []string{"notify-service"}}},
+		}},
+	}
+
+	require.NoError(t, Apply(d, cfg, testLogger))
+
+	assert.Equal(t, "exchange billing-engine topic durable=true", d.calls[0], "the type defaults to topic")
+	assert.Equal(t, amqp.Table{
+		"x-dead-letter-exchange":    "billing-engine",
+		"x-dead-letter-routing-key": "customer.updated",
+		"x-message-ttl":             int64(30000),
+	}, d.args["notify-service.retry"])
+}
+
+func TestApplyStopsAtTheFirstFailure(t *testing.T) {
+	d := &recordingDeclarer{fail: "queue notify-service.customer durable=true"}
+
+	err := Apply(d, config.DefaultTopology("billing-engine"), testLogger)
+
+	assert.ErrorContains(t, err, "failed to declare queue 'notify-service.customer'")
+	assert.Len(t, d.calls, 3)
+}
+
+func TestValidate(t *testing.T) {
+	exchange := []config.ExchangeConfig{{Name: "billing-engine"}}
+	tests := []struct {
+		name string
+		cfg  config.TopologyConfig
+		want string
+	}{
+		{name: "unnamed exchange", cfg: config.TopologyConfig{Exchanges: []config.ExchangeConfig{{}}}, want: "exchange name cannot be empty"},
+		{name: "duplicate exchange", cfg: config.TopologyConfig{Exchanges: append(exchange, exchange...)}, want: `exchange "billing-engine" is declared twice`},
+		{name: "unknown type", cfg: config.TopologyConfig{Exchanges: []config.ExchangeConfig{{Name: "x", Type: "x-delayed"}}}, want: `unknown type "x-delayed"`},
+		{name: "undeclared binding exchange", cfg: config.TopologyConfig{Exchanges: exchange, Queues: []config.QueueConfig{
+			{Name: "q", Bindings: []config.BindingConfig{{Exchange: "other", RoutingKeys: []string{"#"}}}},
+		}}, want: `bound to undeclared exchange "other"`},
+		{name: "binding without keys", cfg: config.TopologyConfig{Exchanges: exchange, Queues: []config.QueueConfig{
+			{Name: "q", Bindings: []config.BindingConfig{{Exchange: "billing-engine"}}},
+		}}, want: "has no routing keys"},
+		{name: "undeclared dead-letter exchange", cfg: config.TopologyConfig{Exchanges: exchange, Queues: []config.QueueConfig{
+			{Name: "q", DeadLetterExchange: "billing-engine.dlx"},
+		}}, want: `dead-letters to undeclared exchange "billing-engine.dlx"`},
+		{name: "dead-letter key alone", cfg: config.TopologyConfig{Queues: []config.QueueConfig{
+			{Name: "q", DeadLetterRoutingKey: "k"},
+		}}, want: "without deadLetterExchange"},
+		{name: "duplicate queue", cfg: config.TopologyConfig{Queues: []config.QueueConfig{{Name: "q"}, {Name: "q"}}}, want: `queue "q" is declared twice`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorContains(t, Validate(tt.cfg), tt.want)
+		})
+	}
+	assert.NoError(t, Validate(config.DefaultTopology("billing-engine")))
+}
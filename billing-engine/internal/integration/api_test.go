@@ -0,0 +1,278 @@
+//go:build integration
+
+package integration
+
+import (
+	"billing-engine/internal/api"
+	"billing-engine/internal/api/handler/dto"
+	"billing-engine/internal/batch"
+	"billing-engine/internal/config"
+	"billing-engine/internal/domain/agreement"
+	"billing-engine/internal/domain/collections"
+	"billing-engine/internal/domain/contact"
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/domain/directdebit"
+	"billing-engine/internal/domain/document"
+	"billing-engine/internal/domain/integrity"
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/domain/note"
+	"billing-engine/internal/domain/overview"
+	"billing-engine/internal/domain/summary"
+	"billing-engine/internal/domain/tenant"
+	"billing-engine/internal/event"
+	"billing-engine/internal/infrastructure/database"
+	"billing-engine/internal/infrastructure/topology"
+	"billing-engine/internal/pkg/clock"
+	"billing-engine/internal/ratelimit"
+	"billing-engine/internal/sandbox"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// apiClient sends authenticated JSON requests to the /v1 routes of a test
+// server.
+type apiClient struct {
+	t      *testing.T
+	server *httptest.Server
+	token  string
+}
+
+func (c *apiClient) do(method, path string, body, out any) int {
+	c.t.Helper()
+	var reader bytes.Buffer
+	if body != nil {
+		require.NoError(c.t, json.NewEncoder(&reader).Encode(body))
+	}
+	req, err := http.NewRequest(method, c.server.URL+"/v1"+path, &reader)
+	require.NoError(c.t, err)
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", c.token)
+	}
+
+	resp, err := c.server.Client().Do(req)
+	require.NoError(c.t, err)
+	defer resp.Body.Close()
+	if out != nil && resp.StatusCode < http.StatusBadRequest {
+		require.NoError(c.t, json.NewDecoder(resp.Body).Decode(out))
+	}
+	return resp.StatusCode
+}
+
+// startServer wires the application the way cmd/main.go does in sandbox
+// mode, on top of the containers, and returns a client holding a staff token.
+func startServer(t *testing.T) *apiClient {
+	t.Helper()
+	ctx := context.Background()
+
+	cfg := &config.Config{}
+	cfg.Server.Auth = config.AuthConfig{Enabled: true, JWTSecret: "integration-secret"}
+	cfg.Server.BodyLimit.DefaultBytes = 1 << 20
+	cfg.Database = config.DatabaseConfig{Driver: database.DriverPostgres, URL: env.Postgres.URL}
+	cfg.Metrics.Path = "/metrics"
+	cfg.Sandbox.Enabled = true
+	billingClock := clock.NewOffset(clock.System())
+
+	repos, err := database.Open(ctx, cfg.Database, billingClock, testLogger)
+	require.NoError(t, err)
+	t.Cleanup(repos.Close)
+
+	hub := event.NewHub(64, 0, testLogger)
+	require.NoError(t, topology.Declare(env.RabbitMQ.Conn, config.TopologyConfig{Exchanges: []config.ExchangeConfig{{Name: exchangeName}}}, testLogger))
+	publisher, err := event.NewRabbitMQEventPublisher(env.RabbitMQ.Conn, exchangeName, testLogger)
+	require.NoError(t, err)
+	eventPublisher := event.NewStreamingPublisher(event.NewRecordingPublisher(publisher, repos.Events, billingClock, testLogger), hub)
+	customerService := customer.NewCustomerService(repos.Customers, eventPublisher, customer.DuplicatePolicy{}, billingClock, testLogger)
+	loanService := loan.NewStreamingLoanService(loan.NewLoanService(repos.Loans, customerService, loan.DefaultPaymentPolicy(), loan.DefaultDelinquencyPolicy(), loan.TaxPolicy{}, loan.CreditPolicy{}, loan.ProductCatalog{}, billingClock, testLogger), hub, billingClock)
+	snapshotService := loan.NewSnapshotService(repos.Snapshots, repos.Loans, testLogger)
+	sandboxService := sandbox.NewService(billingClock, []sandbox.Job{
+		{Name: "delinquency", Run: batch.NewUpdateDelinquencyJob(repos.Loans, loanService, customerService, loan.DefaultDelinquencyPolicy(), billingClock, testLogger).Run},
+		{Name: "snapshot", Run: batch.NewLoanSnapshotJob(snapshotService, billingClock, testLogger).Run},
+	}, testLogger)
+	collectionsService := collections.NewService(repos.Collections, nil, nil, billingClock, testLogger)
+	contactService := contact.NewService(repos.Contacts, eventPublisher, billingClock, testLogger)
+	documentService := document.NewService(repos.Documents, nil, event.NewBuffer(eventPublisher, 0, 0, testLogger), document.Config{}, billingClock, testLogger)
+	router := api.SetupRouter(
+		loanService,
+		customerService,
+		customer.NewImportService(repos.Customers, nil, 500, nil, testLogger),
+		note.NewService(repos.Notes, nil, testLogger),
+		documentService,
+		agreement.NewService(repos.Agreements, loanService, customerService, contactService, documentService, nil, billingClock, testLogger),
+		snapshotService,
+		directdebit.NewService(repos.DirectDebits, loanService, directdebit.Config{
+			Format: directdebit.FormatCSV, HorizonDays: directdebit.DefaultHorizonDays, Currency: "IDR", MinorUnitDigits: 2,
+		}, billingClock, testLogger),
+		contactService,
+		collectionsService,
+		summary.NewService(repos.Summaries, testLogger),
+		overview.NewService(customerService, loanService, collectionsService, nil, testLogger),
+		integrity.NewService(repos.Integrity, billingClock, testLogger),
+		tenant.NewService(repos.Tenants, tenant.Defaults{
+			Delinquency: loan.DefaultDelinquencyPolicy(), RateLimitRPS: cfg.Server.RateLimit.RPS, RateLimitBurst: cfg.Server.RateLimit.Burst,
+		}, 0, billingClock, testLogger),
+		hub, event.NewReplayService(repos.Events, publisher.(event.RawPublisher), billingClock, testLogger), billingClock, sandboxService,
+		ratelimit.NewAccessList(ratelimit.NewMemoryStore(), 0, testLogger), nil, nil, nil, cfg, testLogger,
+	)
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	client := &apiClient{t: t, server: server}
+	var token map[string]string
+	require.Equal(t, http.StatusOK, client.do(http.MethodPost, "/auth/token", dto.TokenRequest{Username: "integration"}, &token))
+	client.token = token["token"]
+	return client
+}
+
+func TestAPILoanLifecycle(t *testing.T) {
+	resetDatabase(t)
+	client := startServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	deliveries, err := env.RabbitMQ.Subscribe(ctx, exchangeName, "customer.created")
+	require.NoError(t, err)
+
+	anonymous := &apiClient{t: t, server: client.server}
+	assert.Equal(t, http.StatusUnauthorized, anonymous.do(http.MethodGet, "/customers/1", nil, nil))
+
+	var cust dto.CustomerResponse
+	require.Equal(t, http.StatusCreated, client.do(http.MethodPost, "/customers", dto.CreateCustomerRequest{
+		Name: "Jane Doe", Address: "1 Main St",
+	}, &cust))
+
+	var createdEvent event.CustomerCreatedEvent
+	receive(t, deliveries, &createdEvent)
+	assert.Equal(t, "Jane Doe", createdEvent.Payload.Name)
+
+	var created dto.LoanResponse
+	require.Equal(t, http.StatusCreated, client.do(http.MethodPost, "/loans", map[string]any{
+		"customerId":         createdEvent.Payload.CustomerID,
+		"principal":          5000000,
+		"termWeeks":          50,
+		"annualInterestRate": 0.1,
+		"startDate":          "2025-01-06",
+	}, &created))
+	assert.Equal(t, "ACTIVE", created.Status)
+	assert.Equal(t, "5500000.00", created.TotalLoanAmount)
+
+	var linked dto.CustomerResponse
+	require.Equal(t, http.StatusOK, client.do(http.MethodGet, "/customers/"+cust.CustomerID, nil, &linked))
+	require.NotNil(t, linked.LoanID)
+	assert.Equal(t, created.ID, *linked.LoanID)
+
+	var outstanding dto.OutstandingResponse
+	require.Equal(t, http.StatusOK, client.do(http.MethodGet, "/loans/"+created.ID+"/outstanding", nil, &outstanding))
+	assert.Equal(t, "5500000.00", outstanding.OutstandingAmount)
+
+	assert.Equal(t, http.StatusBadRequest, client.do(http.MethodPost, "/loans/"+created.ID+"/payments", dto.MakePaymentRequest{Amount: "1"}, nil),
+		"only the weekly amount is accepted")
+	payment := dto.MakePaymentRequest{Amount: created.WeeklyPaymentAmount, Channel: "GATEWAY", Reference: "PG-1"}
+	require.Equal(t, http.StatusOK, client.do(http.MethodPost, "/loans/"+created.ID+"/payments", payment, nil))
+	assert.Equal(t, http.StatusConflict, client.do(http.MethodPost, "/loans/"+created.ID+"/payments", payment, nil),
+		"a gateway reference is posted once")
+
+	require.Equal(t, http.StatusOK, client.do(http.MethodGet, "/loans/"+created.ID+"/outstanding", nil, &outstanding))
+	assert.Equal(t, "5390000.00", outstanding.OutstandingAmount)
+
+	var collections dto.CollectionsByChannelResponse
+	require.Equal(t, http.StatusOK, client.do(http.MethodGet, "/reports/collections-by-channel", nil, &collections))
+	assert.Equal(t, 1, collections.Payments)
+	assert.Contains(t, collections.ByChannel, dto.ChannelCollectionsResponse{Channel: "GATEWAY", Payments: 1, Amount: "110000.00"})
+}
+
+func TestAPITenantIsolation(t *testing.T) {
+	resetDatabase(t)
+	client := startServer(t)
+	tenantClient := func(tenantID string) *apiClient {
+		signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"username": "staff-" + tenantID, "tenant": tenantID, "exp": time.Now().Add(time.Hour).Unix()}).SignedString([]byte("integration-secret"))
+		require.NoError(t, err)
+		return &apiClient{t: t, server: client.server, token: "Bearer " + signed}
+	}
+	tenantA, tenantB := tenantClient("tenant-a"), tenantClient("tenant-b")
+
+	var cust dto.CustomerResponse
+	require.Equal(t, http.StatusCreated, tenantB.do(http.MethodPost, "/customers", dto.CreateCustomerRequest{Name: "Bob Roe", Address: "2 Main St"}, &cust))
+	var created dto.LoanResponse
+	require.Equal(t, http.StatusCreated, tenantB.do(http.MethodPost, "/loans", map[string]any{
+		"customerId":         cust.CustomerID,
+		"principal":          5000000,
+		"termWeeks":          50,
+		"annualInterestRate": 0.1,
+		"startDate":          "2025-01-06",
+	}, &created))
+
+	assert.Equal(t, http.StatusNotFound, tenantA.do(http.MethodGet, "/loans/"+created.ID, nil, nil), "a tenant-a token cannot read a tenant-b loan")
+	assert.Equal(t, http.StatusNotFound, tenantA.do(http.MethodGet, "/loans/"+created.PublicID, nil, nil))
+	assert.Equal(t, http.StatusNotFound, tenantA.do(http.MethodGet, "/loans/"+created.ID+"/outstanding", nil, nil))
+	assert.Equal(t, http.StatusNotFound, tenantA.do(http.MethodPost, "/loans/"+created.ID+"/payments",
+		dto.MakePaymentRequest{Amount: created.WeeklyPaymentAmount}, nil))
+	assert.Equal(t, http.StatusNotFound, tenantA.do(http.MethodGet, "/customers/"+cust.CustomerID, nil, nil))
+	assert.Equal(t, http.StatusNotFound, client.do(http.MethodGet, "/loans/"+created.ID, nil, nil), "tokens without a tenant work for the default tenant")
+
+	assert.Equal(t, http.StatusOK, tenantB.do(http.MethodGet, "/loans/"+created.ID, nil, nil))
+	assert.Equal(t, http.StatusOK, tenantB.do(http.MethodGet, "/customers/"+cust.CustomerID, nil, nil))
+}
+
+func TestAPISandboxTimeTravel(t *testing.T) {
+	resetDatabase(t)
+	client := startServer(t)
+
+	var clockState dto.SandboxClockResponse
+	assert.Equal(t, http.StatusForbidden, client.do(http.MethodGet, "/admin/sandbox/clock", nil, nil), "a staff token is not enough")
+
+	var token map[string]string
+	assert.Equal(t, http.StatusForbidden, client.do(http.MethodPost, "/auth/token", dto.TokenRequest{Username: "uat", Admin: true}, nil), "only an admin hands out the admin scope")
+	// The first admin token is signed by the operator, as it would be with
+	// the configured secret.
+	operator, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"username": "operator", "scope": "admin", "exp": time.Now().Add(time.Hour).Unix()}).SignedString([]byte("integration-secret"))
+	require.NoError(t, err)
+	bootstrap := &apiClient{t: t, server: client.server, token: "Bearer " + operator}
+	require.Equal(t, http.StatusOK, bootstrap.do(http.MethodPost, "/auth/token", dto.TokenRequest{Username: "uat", Admin: true}, &token))
+	admin := &apiClient{t: t, server: client.server, token: token["token"]}
+	require.Equal(t, http.StatusOK, admin.do(http.MethodGet, "/admin/sandbox/clock", nil, &clockState))
+	assert.Equal(t, 0, clockState.OffsetDays)
+
+	var cust dto.CustomerResponse
+	require.Equal(t, http.StatusCreated, client.do(http.MethodPost, "/customers", dto.CreateCustomerRequest{Name: "Jane Doe", Address: "1 Main St"}, &cust))
+	customerID, err := strconv.ParseInt(cust.CustomerID, 10, 64)
+	require.NoError(t, err)
+	var created dto.LoanResponse
+	require.Equal(t, http.StatusCreated, client.do(http.MethodPost, "/loans", map[string]any{
+		"customerId":         customerID,
+		"principal":          5000000,
+		"termWeeks":          50,
+		"annualInterestRate": 0.1,
+		"startDate":          clockState.Today,
+	}, &created))
+
+	var delinquent dto.DelinquentResponse
+	require.Equal(t, http.StatusOK, client.do(http.MethodGet, "/loans/"+created.ID+"/delinquent", nil, &delinquent))
+	assert.False(t, delinquent.IsDelinquent)
+
+	require.Equal(t, http.StatusOK, admin.do(http.MethodPost, "/admin/sandbox/clock/advance", dto.AdvanceClockRequest{Days: 15}, &clockState))
+	assert.Equal(t, 15, clockState.OffsetDays)
+
+	require.Equal(t, http.StatusOK, client.do(http.MethodGet, "/loans/"+created.ID+"/delinquent", nil, &delinquent))
+	assert.True(t, delinquent.IsDelinquent, "two missed weeks make the loan delinquent")
+	var linked dto.CustomerResponse
+	require.Equal(t, http.StatusOK, client.do(http.MethodGet, "/customers/"+cust.CustomerID, nil, &linked))
+	assert.True(t, linked.IsDelinquent, "the delinquency job ran on the simulated days")
+	var overdue dto.LoanResponse
+	require.Equal(t, http.StatusOK, client.do(http.MethodGet, "/loans/"+created.ID, nil, &overdue))
+	assert.Equal(t, 8, overdue.DaysPastDue, "the first installment fell due a week after the start")
+
+	var history dto.LoanHistoryResponse
+	require.Equal(t, http.StatusOK, client.do(http.MethodGet, "/loans/"+created.ID+"/history", nil, &history))
+	assert.Len(t, history.Snapshots, 15, "one snapshot per simulated day")
+}
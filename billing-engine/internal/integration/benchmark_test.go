@@ -0,0 +1,102 @@
+//go:build integration
+
+package integration
+
+import (
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/infrastructure/database/postgres"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"testing"
+	"time"
+)
+
+// createBenchmarkLoan stores a customer and a default loan of termWeeks
+// installments, the shape of the loans the reads and payments below work on.
+func createBenchmarkLoan(b *testing.B, repo *postgres.LoanRepository, termWeeks int) *loan.Loan {
+	b.Helper()
+	ctx := context.Background()
+	cust := customer.NewCustomer("Jane Doe", "1 Main St")
+	if err := postgres.NewCustomerRepository(env.Postgres.Pool, clock.System(), testLogger).Save(ctx, cust); err != nil {
+		b.Fatal(err)
+	}
+	newLoan, err := loan.NewLoan(loan.DefaultPrincipal, termWeeks, loan.DefaultInterestRate, day("2025-01-06"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	schedule, err := newLoan.GenerateSchedule()
+	if err != nil {
+		b.Fatal(err)
+	}
+	created, err := repo.CreateLoan(ctx, cust.CustomerID, newLoan, schedule)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return created
+}
+
+func BenchmarkLoanRepositoryReads(b *testing.B) {
+	resetDatabase(b)
+	ctx := context.Background()
+	repo := postgres.NewLoanRepository(env.Postgres.Pool, clock.System(), testLogger)
+	created := createBenchmarkLoan(b, repo, loan.DefaultTermWeeks)
+
+	reads := []struct {
+		name string
+		read func() error
+	}{
+		{"GetLoanByID", func() error { _, err := repo.GetLoanByID(ctx, created.ID); return err }},
+		{"GetLoanWithSchedule", func() error { _, err := repo.GetLoanWithSchedule(ctx, created.ID); return err }},
+		{"GetUnpaidSchedules", func() error { _, err := repo.GetUnpaidSchedules(ctx, created.ID); return err }},
+		{"ListBalanceItems", func() error { _, err := repo.ListBalanceItems(ctx, created.ID); return err }},
+	}
+	for _, r := range reads {
+		b.Run(r.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				if err := r.read(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkLoanRepositoryPayment runs the statements of one payment in its
+// transaction. Each iteration pays a cent towards the oldest installment so
+// that the loan never runs out of installments to pay.
+func BenchmarkLoanRepositoryPayment(b *testing.B) {
+	resetDatabase(b)
+	ctx := context.Background()
+	repo := postgres.NewLoanRepository(env.Postgres.Pool, clock.System(), testLogger)
+	created := createBenchmarkLoan(b, repo, loan.DefaultTermWeeks)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		err := repo.WithinTransaction(ctx, func(tx loan.TxRepository) error {
+			if err := tx.LockLoanForPayment(ctx, created.ID); err != nil {
+				return err
+			}
+			entry, err := tx.FindOldestUnpaidEntryForUpdate(ctx, created.ID)
+			if err != nil {
+				return err
+			}
+			paidAt := time.Now()
+			entry.PaidAmount += 0.01
+			entry.PaymentDate = &paidAt
+			if err := tx.UpdateScheduleEntry(ctx, entry); err != nil {
+				return err
+			}
+			payment := &loan.Payment{LoanID: created.ID, ScheduleID: &entry.ID, Amount: 0.01, Channel: loan.ChannelBankTransfer, PaidAt: paidAt}
+			if err := tx.RecordPayment(ctx, payment); err != nil {
+				return err
+			}
+			_, err = tx.CheckIfAllPaymentsMade(ctx, created.ID)
+			return err
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+//go:build integration
+
+package integration
+
+import (
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/event"
+	"billing-engine/internal/infrastructure/database/postgres"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const exchangeName = "billing-engine"
+
+// receive waits for the next delivery and decodes it into v.
+func receive(t *testing.T, deliveries <-chan amqp.Delivery, v any) amqp.Delivery {
+	t.Helper()
+	select {
+	case d, ok := <-deliveries:
+		require.True(t, ok, "subscription closed")
+		require.NoError(t, json.Unmarshal(d.Body, v))
+		return d
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+	return amqp.Delivery{}
+}
+
+func TestCustomerEventsArePublished(t *testing.T) {
+	resetDatabase(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	deliveries, err := env.RabbitMQ.Subscribe(ctx, exchangeName, "customer.*")
+	require.NoError(t, err)
+
+	publisher, err := event.NewRabbitMQEventPublisher(env.RabbitMQ.Conn, exchangeName, testLogger)
+	require.NoError(t, err)
+	svc := customer.NewCustomerService(postgres.NewCustomerRepository(env.Postgres.Pool, clock.System(), testLogger), publisher, customer.DuplicatePolicy{}, clock.System(), testLogger)
+
+	created, err := svc.CreateNewCustomer(ctx, "Jane Doe", "1 Main St", "", uuid.Nil, false)
+	require.NoError(t, err)
+
+	var createdEvent event.CustomerCreatedEvent
+	d := receive(t, deliveries, &createdEvent)
+	assert.Equal(t, "customer.created", d.RoutingKey)
+	assert.Equal(t, "application/json", d.ContentType)
+	assert.NotEmpty(t, createdEvent.EventID)
+	assert.Equal(t, createdEvent.EventID, d.MessageId)
+	assert.Equal(t, created.CustomerID, createdEvent.Payload.CustomerID)
+	assert.Equal(t, "Jane Doe", createdEvent.Payload.Name)
+
+	require.NoError(t, svc.UpdateCustomerAddress(ctx, created.CustomerID, "2 Main St"))
+
+	var updatedEvent event.CustomerUpdatedEvent
+	d = receive(t, deliveries, &updatedEvent)
+	assert.Equal(t, "customer.updated", d.RoutingKey)
+	assert.Equal(t, "2 Main St", updatedEvent.Payload.Address)
+}
@@ -0,0 +1,49 @@
+//go:build integration
+
+// Package integration runs the repositories, the event publisher and the HTTP
+// API against real PostgreSQL and RabbitMQ containers.
+package integration
+
+import (
+	"billing-engine/internal/testutil"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+)
+
+var (
+	env        *testutil.Env
+	testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+)
+
+func TestMain(m *testing.M) {
+	ctx := context.Background()
+	if err := testutil.DockerAvailable(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, "skipping integration tests, docker is not available:", err)
+		os.Exit(0)
+	}
+
+	var err error
+	env, err = testutil.Start(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to start integration environment:", err)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+	if err := env.Close(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to stop integration environment:", err)
+	}
+	os.Exit(code)
+}
+
+// resetDatabase gives the calling test an empty, migrated database.
+func resetDatabase(t testing.TB) {
+	t.Helper()
+	if err := env.Postgres.Reset(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,352 @@
+//go:build integration
+
+package integration
+
+import (
+	"billing-engine/internal/config"
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/infrastructure/database/postgres"
+	"billing-engine/internal/jobs"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"billing-engine/internal/pkg/scope"
+	"context"
+	"encoding/json"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func day(s string) time.Time {
+	d, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// createTestLoan stores a customer and a three week loan starting on start
+// and returns the customer ID and the loan.
+func createTestLoan(t testing.TB, start time.Time, externalRef string) (int64, *loan.Loan) {
+	t.Helper()
+	ctx := context.Background()
+	cust := customer.NewCustomer("Jane Doe", "1 Main St")
+	require.NoError(t, postgres.NewCustomerRepository(env.Postgres.Pool, clock.System(), testLogger).Save(ctx, cust))
+
+	newLoan := &loan.Loan{
+		PrincipalAmount:     300,
+		InterestRate:        0.1,
+		TermWeeks:           3,
+		WeeklyPaymentAmount: 110,
+		TotalLoanAmount:     330,
+		StartDate:           start,
+		Status:              loan.StatusActive,
+	}
+	if externalRef != "" {
+		newLoan.ExternalRef = &externalRef
+	}
+	schedule := make([]loan.ScheduleEntry, 3)
+	for i := range schedule {
+		schedule[i] = loan.ScheduleEntry{
+			WeekNumber: i + 1,
+			DueDate:    start.AddDate(0, 0, 7*(i+1)),
+			DueAmount:  110,
+			Status:     loan.PaymentStatusPending,
+		}
+	}
+	created, err := postgres.NewLoanRepository(env.Postgres.Pool, clock.System(), testLogger).CreateLoan(ctx, cust.CustomerID, newLoan, schedule)
+	require.NoError(t, err)
+	return cust.CustomerID, created
+}
+
+func TestLoanRepository(t *testing.T) {
+	resetDatabase(t)
+	repo := postgres.NewLoanRepository(env.Postgres.Pool, clock.System(), testLogger)
+	ctx := context.Background()
+
+	customerID, created := createTestLoan(t, day("2025-01-06"), "ref-1")
+	assert.NotZero(t, created.ID)
+	assert.Equal(t, loan.StatusActive, created.Status)
+
+	byRef, err := repo.GetLoanByExternalRef(ctx, "ref-1")
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, byRef.ID)
+
+	_, err = repo.GetLoanByID(ctx, created.ID+1)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+
+	linked, err := postgres.NewCustomerRepository(env.Postgres.Pool, clock.System(), testLogger).FindByLoanID(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, customerID, linked.CustomerID)
+
+	_, err = repo.CreateLoan(ctx, customerID, &loan.Loan{
+		PrincipalAmount: 100, TermWeeks: 1, TotalLoanAmount: 100, StartDate: day("2025-01-06"), Status: loan.StatusActive,
+	}, nil)
+	assert.ErrorIs(t, err, apperrors.ErrConflict, "a customer holds one loan at a time")
+
+	for week := 1; week <= 3; week++ {
+		require.NoError(t, repo.WithinTransaction(ctx, func(tx loan.TxRepository) error {
+			entry, err := tx.FindOldestUnpaidEntryForUpdate(ctx, created.ID)
+			require.NoError(t, err)
+			assert.Equal(t, week, entry.WeekNumber)
+
+			paidAt := time.Now()
+			entry.Status = loan.PaymentStatusPaid
+			entry.PaidAmount = entry.DueAmount
+			entry.PaymentDate = &paidAt
+			require.NoError(t, tx.UpdateScheduleEntry(ctx, entry))
+
+			allPaid, err := tx.CheckIfAllPaymentsMade(ctx, created.ID)
+			require.NoError(t, err)
+			if allPaid {
+				return tx.UpdateLoanStatus(ctx, created.ID, loan.StatusPaidOff)
+			}
+			return nil
+		}))
+	}
+
+	paidOff, err := repo.GetLoanByID(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, loan.StatusPaidOff, paidOff.Status)
+
+	current, err := repo.GetScheduleByLoanID(ctx, created.ID)
+	require.NoError(t, err)
+	items, err := repo.ListBalanceItems(ctx, created.ID)
+	require.NoError(t, err)
+	outstanding := loan.CalculateOutstanding(paidOff, current, items, time.Now(), loan.DefaultPaymentPolicy(), loan.TaxPolicy{})
+	assert.Zero(t, outstanding.Total)
+
+	active, err := repo.GetActiveLoanTenants(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, active)
+}
+
+// TestRowLevelSecurity checks the policies themselves with plain SQL, which
+// the repositories' own tenant filters cannot hide. Superusers skip the
+// policies, so the pool logs in as a role of its own.
+func TestRowLevelSecurity(t *testing.T) {
+	resetDatabase(t)
+	ctx := context.Background()
+	_, err := env.Postgres.Pool.Exec(ctx, `
+        DO $$ BEGIN
+            IF NOT EXISTS (SELECT FROM pg_roles WHERE rolname = 'billing_tenant') THEN
+                CREATE ROLE billing_tenant LOGIN PASSWORD 'password' NOSUPERUSER NOBYPASSRLS;
+            END IF;
+        END $$;
+        GRANT SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA public TO billing_tenant;
+        GRANT USAGE, SELECT ON ALL SEQUENCES IN SCHEMA public TO billing_tenant`)
+	require.NoError(t, err)
+
+	_, err = postgres.NewTenantConnectionPool(ctx, config.DatabaseConfig{URL: env.Postgres.URL}, testLogger)
+	assert.Error(t, err, "a superuser would skip every policy")
+
+	tenantURL, err := url.Parse(env.Postgres.URL)
+	require.NoError(t, err)
+	tenantURL.User = url.UserPassword("billing_tenant", "password")
+	pool, err := postgres.NewTenantConnectionPool(ctx, config.DatabaseConfig{URL: tenantURL.String()}, testLogger)
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+
+	tenantA := scope.WithTenant(ctx, "tenant-a")
+	tenantB := scope.WithTenant(ctx, "tenant-b")
+	customers := postgres.NewCustomerRepository(pool, clock.System(), testLogger)
+	require.NoError(t, customers.Save(tenantA, customer.NewCustomer("Jane Doe", "1 Main St")))
+	require.NoError(t, customers.Save(tenantB, customer.NewCustomer("Bob Roe", "2 Main St")))
+
+	count := func(ctx context.Context) int {
+		var n int
+		require.NoError(t, pool.QueryRow(ctx, `SELECT COUNT(*) FROM customers`).Scan(&n))
+		return n
+	}
+	assert.Equal(t, 1, count(tenantA), "tenant-a sees its own customer only")
+	assert.Equal(t, 1, count(tenantB))
+	assert.Equal(t, 2, count(ctx), "an unscoped caller, such as a batch job, sees every tenant")
+
+	tag, err := pool.Exec(tenantA, `UPDATE customers SET name = 'Taken' WHERE tenant_id = 'tenant-b'`)
+	require.NoError(t, err)
+	assert.Zero(t, tag.RowsAffected(), "tenant-a cannot change tenant-b's rows")
+	_, err = pool.Exec(tenantA, `UPDATE customers SET tenant_id = 'tenant-b'`)
+	assert.Error(t, err, "tenant-a cannot hand its rows to tenant-b")
+}
+
+func TestLoanPaymentLock(t *testing.T) {
+	resetDatabase(t)
+	repo := postgres.NewLoanRepository(env.Postgres.Pool, clock.System(), testLogger)
+	ctx := context.Background()
+	_, created := createTestLoan(t, day("2025-01-06"), "")
+	_, other := createTestLoan(t, day("2025-01-06"), "")
+
+	require.NoError(t, repo.WithinTransaction(ctx, func(first loan.TxRepository) error {
+		require.NoError(t, first.LockLoanForPayment(ctx, created.ID))
+
+		return repo.WithinTransaction(ctx, func(second loan.TxRepository) error {
+			assert.ErrorIs(t, second.LockLoanForPayment(ctx, created.ID), apperrors.ErrConflict)
+			assert.NoError(t, second.LockLoanForPayment(ctx, other.ID), "other loans are not locked")
+			return nil
+		})
+	}))
+
+	require.NoError(t, repo.WithinTransaction(ctx, func(third loan.TxRepository) error {
+		assert.NoError(t, third.LockLoanForPayment(ctx, created.ID), "the lock ends with the transaction")
+		return nil
+	}))
+}
+
+func TestLoanSnapshotRepository(t *testing.T) {
+	resetDatabase(t)
+	repo := postgres.NewLoanRepository(env.Postgres.Pool, clock.System(), testLogger)
+	ctx := context.Background()
+	_, created := createTestLoan(t, time.Now().UTC().AddDate(0, 0, -30), "")
+
+	asOf := time.Now().UTC()
+	written, err := repo.WriteDailySnapshots(ctx, asOf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), written)
+
+	snapshots, err := repo.GetSnapshots(ctx, created.ID, asOf.AddDate(0, 0, -7), asOf)
+	require.NoError(t, err)
+	require.Len(t, snapshots, 1)
+	assert.InDelta(t, 330, snapshots[0].Outstanding, 0.001)
+	assert.Equal(t, 23, snapshots[0].DPD)
+}
+
+func TestCustomerImportRepository(t *testing.T) {
+	resetDatabase(t)
+	repo := postgres.NewCustomerRepository(env.Postgres.Pool, clock.System(), testLogger)
+	ctx := context.Background()
+
+	ids, err := repo.InsertImportBatch(ctx, []customer.ImportRow{
+		{Line: 2, Name: "Jane Doe", Address: "1 Main St", ExternalRef: "crm-1"},
+		{Line: 3, Name: "John Doe", Address: "2 Main St", ExternalRef: "crm-2"},
+	})
+	require.NoError(t, err)
+	require.Len(t, ids, 2)
+	assert.NotZero(t, ids[0])
+	assert.NotZero(t, ids[1])
+
+	ids, err = repo.InsertImportBatch(ctx, []customer.ImportRow{
+		{Line: 2, Name: "Jane Doe", Address: "1 Main St", ExternalRef: "crm-1"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{0}, ids, "rows whose external ref exists are skipped")
+
+	imported, err := repo.FindByExternalRef(ctx, "crm-2")
+	require.NoError(t, err)
+	assert.Equal(t, "John Doe", imported.Name)
+}
+
+// TestRepositoryTimestampsFromClock checks that the updated_at trigger keeps
+// the timestamp the repository writes instead of replacing it with NOW().
+func TestRepositoryTimestampsFromClock(t *testing.T) {
+	resetDatabase(t)
+	clk := clock.NewFake(time.Date(2025, 1, 6, 9, 30, 0, 0, time.UTC))
+	repo := postgres.NewCustomerRepository(env.Postgres.Pool, clk, testLogger)
+	ctx := context.Background()
+
+	cust := customer.NewCustomer("Jane Doe", "1 Main St")
+	require.NoError(t, repo.Save(ctx, cust))
+	clk.Advance(time.Hour)
+	require.NoError(t, repo.SetDelinquencyStatus(ctx, cust.CustomerID, true))
+
+	stored, err := repo.FindByID(ctx, cust.CustomerID)
+	require.NoError(t, err)
+	assert.True(t, stored.CreateDate.Equal(time.Date(2025, 1, 6, 9, 30, 0, 0, time.UTC)))
+	assert.True(t, stored.UpdatedAt.Equal(time.Date(2025, 1, 6, 10, 30, 0, 0, time.UTC)))
+}
+
+func TestPartitionedPayments(t *testing.T) {
+	resetDatabase(t)
+	repo := postgres.NewLoanRepository(env.Postgres.Pool, clock.System(), testLogger)
+	ctx := context.Background()
+	_, created := createTestLoan(t, day("2025-01-06"), "")
+	schedule, err := repo.GetScheduleByLoanID(ctx, created.ID)
+	require.NoError(t, err)
+
+	_, err = repo.EnsurePartitions(ctx, time.Now(), 3)
+	require.NoError(t, err)
+	_, err = repo.EnsurePartitions(ctx, time.Now(), 3)
+	require.NoError(t, err, "a second run finds everything in place")
+
+	reference := "TRX-1"
+	record := func(entry loan.ScheduleEntry, paidAt time.Time) error {
+		return repo.WithinTransaction(ctx, func(tx loan.TxRepository) error {
+			return tx.RecordPayment(ctx, &loan.Payment{LoanID: created.ID, ScheduleID: &entry.ID, Amount: 110,
+				Channel: loan.ChannelBankTransfer, Reference: &reference, PaidAt: paidAt})
+		})
+	}
+	require.NoError(t, record(schedule[0], time.Now()))
+	assert.ErrorIs(t, record(schedule[1], time.Now().AddDate(0, 2, 0)), apperrors.ErrAlreadyExists,
+		"a reference is unique across partitions")
+
+	var partition string
+	require.NoError(t, env.Postgres.Pool.QueryRow(ctx, `SELECT tableoid::regclass::text FROM payments WHERE loan_id = $1`, created.ID).Scan(&partition))
+	assert.NotEqual(t, "payments_default", partition)
+	require.NoError(t, env.Postgres.Pool.QueryRow(ctx, `SELECT tableoid::regclass::text FROM loan_schedule WHERE loan_id = $1 LIMIT 1`, created.ID).Scan(&partition))
+	assert.NotEqual(t, "loan_schedule_default", partition)
+}
+
+func TestJobRepository(t *testing.T) {
+	resetDatabase(t)
+	repo := postgres.NewJobRepository(env.Postgres.Pool, testLogger)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	for i := range 2 {
+		require.NoError(t, repo.Create(ctx, &jobs.Job{ID: uuid.NewString(), Kind: "customer-import", Status: jobs.StatusQueued,
+			Payload: json.RawMessage(`{"rows": 1}`), Total: 1, CreatedAt: now.Add(time.Duration(i) * time.Millisecond)}))
+	}
+	scheduled := &jobs.Job{ID: uuid.NewString(), Kind: "batch.LoanSnapshot", Status: jobs.StatusQueued,
+		DedupeKey: "batch.LoanSnapshot@2025-01-06T23:50:00Z", CreatedAt: now}
+	require.NoError(t, repo.Create(ctx, scheduled))
+	scheduled.ID = uuid.NewString()
+	assert.ErrorIs(t, repo.Create(ctx, scheduled), apperrors.ErrAlreadyExists)
+
+	// Two workers claiming at once get different jobs.
+	var wg sync.WaitGroup
+	claimed := make([]*jobs.Job, 2)
+	for i := range claimed {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			job, err := repo.Claim(ctx, []string{"customer-import"}, "worker", now, now.Add(-time.Minute))
+			assert.NoError(t, err)
+			claimed[i] = job
+		}()
+	}
+	wg.Wait()
+	require.NotNil(t, claimed[0])
+	require.NotNil(t, claimed[1])
+	assert.NotEqual(t, claimed[0].ID, claimed[1].ID)
+	none, err := repo.Claim(ctx, []string{"customer-import"}, "worker", now, now.Add(-time.Minute))
+	require.NoError(t, err)
+	assert.Nil(t, none)
+
+	finishedAt := now.Add(time.Second)
+	done := claimed[0]
+	done.Status, done.Result, done.Processed, done.FinishedAt = jobs.StatusSucceeded, json.RawMessage(`{"created": 1}`), 1, &finishedAt
+	require.NoError(t, repo.Finish(ctx, done))
+	stored, err := repo.Get(ctx, done.ID)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"created": 1}`, string(stored.Result))
+	assert.Nil(t, stored.Payload)
+
+	// A lease that ran out makes the job claimable again.
+	later := now.Add(5 * time.Minute)
+	reclaimed, err := repo.Claim(ctx, []string{"customer-import"}, "other", later, later.Add(-time.Minute))
+	require.NoError(t, err)
+	require.NotNil(t, reclaimed)
+	assert.Equal(t, claimed[1].ID, reclaimed.ID)
+	assert.Equal(t, 2, reclaimed.Attempts)
+
+	queued, err := repo.CountQueued(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, queued)
+	deleted, err := repo.DeleteFinishedBefore(ctx, finishedAt.Add(time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+}
@@ -0,0 +1,65 @@
+//go:build integration
+
+package integration
+
+import (
+	"billing-engine/internal/config"
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/event"
+	"billing-engine/internal/infrastructure/database/postgres"
+	"billing-engine/internal/infrastructure/topology"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultTopologyRoutesAndDeadLettersEvents(t *testing.T) {
+	resetDatabase(t)
+	ctx := context.Background()
+	cfg := config.DefaultTopology(exchangeName)
+
+	require.NoError(t, topology.Declare(env.RabbitMQ.Conn, cfg, testLogger))
+	require.NoError(t, topology.Declare(env.RabbitMQ.Conn, cfg, testLogger), "declaring again is a no-op")
+
+	ch, err := env.RabbitMQ.Conn.Channel()
+	require.NoError(t, err)
+	defer ch.Close()
+	for _, q := range cfg.Queues {
+		_, err := ch.QueuePurge(q.Name, false)
+		require.NoError(t, err)
+	}
+
+	publisher, err := event.NewRabbitMQEventPublisher(env.RabbitMQ.Conn, exchangeName, testLogger)
+	require.NoError(t, err)
+	svc := customer.NewCustomerService(postgres.NewCustomerRepository(env.Postgres.Pool, clock.System(), testLogger), publisher, customer.DuplicatePolicy{}, clock.System(), testLogger)
+	_, err = svc.CreateNewCustomer(ctx, "Jane Doe", "1 Main St", "", uuid.Nil, false)
+	require.NoError(t, err)
+
+	var got bool
+	require.Eventually(t, func() bool {
+		d, ok, err := ch.Get("notify-service.customer", false)
+		require.NoError(t, err)
+		if ok {
+			assert.Equal(t, "customer.created", d.RoutingKey)
+			require.NoError(t, d.Nack(false, false))
+			got = true
+		}
+		return ok
+	}, 10*time.Second, 50*time.Millisecond)
+	require.True(t, got)
+
+	require.Eventually(t, func() bool {
+		d, ok, err := ch.Get("notify-service.customer.dlq", true)
+		require.NoError(t, err)
+		return ok && d.RoutingKey == "customer.created"
+	}, 10*time.Second, 50*time.Millisecond, "a rejected message lands in the customer DLQ")
+
+	q, err := ch.QueueDeclarePassive("notify-service.loan", true, false, false, false, nil)
+	require.NoError(t, err)
+	assert.Zero(t, q.Messages, "customer events are not routed to the loan queue")
+}
@@ -0,0 +1,55 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Locker hands out named locks that every instance sharing it respects. A
+// lock is a lease: it lapses at its expiry unless its owner extends it, so
+// an instance that stops cannot hold one for good.
+type Locker interface {
+	// TryLock takes the lock called name for owner until expires, or extends
+	// it when owner holds it already. It reports false while another owner
+	// holds it past now.
+	TryLock(ctx context.Context, name, owner string, now, expires time.Time) (bool, error)
+	// Unlock releases the lock called name if owner holds it.
+	Unlock(ctx context.Context, name, owner string) error
+}
+
+// MemoryLocker keeps the locks of a single instance in memory.
+type MemoryLocker struct {
+	mu    sync.Mutex
+	locks map[string]memoryLock
+}
+
+type memoryLock struct {
+	owner   string
+	expires time.Time
+}
+
+var _ Locker = (*MemoryLocker)(nil)
+
+func NewMemoryLocker() *MemoryLocker {
+	return &MemoryLocker{locks: map[string]memoryLock{}}
+}
+
+func (l *MemoryLocker) TryLock(_ context.Context, name, owner string, now, expires time.Time) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if held, ok := l.locks[name]; ok && held.owner != owner && held.expires.After(now) {
+		return false, nil
+	}
+	l.locks[name] = memoryLock{owner: owner, expires: expires}
+	return true, nil
+}
+
+func (l *MemoryLocker) Unlock(_ context.Context, name, owner string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if held, ok := l.locks[name]; ok && held.owner == owner {
+		delete(l.locks, name)
+	}
+	return nil
+}
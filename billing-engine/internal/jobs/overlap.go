@@ -0,0 +1,156 @@
+package jobs
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OverlapPolicy is what a batch job does when its next run is due while the
+// previous one, on any instance, is still going.
+type OverlapPolicy string
+
+const (
+	// OverlapSkip drops the new run.
+	OverlapSkip OverlapPolicy = "skip"
+	// OverlapQueue holds the new run until the previous one ends.
+	OverlapQueue OverlapPolicy = "queue"
+	// OverlapAllow lets both run at once.
+	OverlapAllow OverlapPolicy = "allow"
+)
+
+// ErrRunOverlapped is returned by OverlapGuard.Run for a run it dropped
+// because the previous run still holds the job.
+var ErrRunOverlapped = errors.New("the previous run is still running")
+
+// ParseOverlapPolicy reads a policy name, ignoring case. An empty name is
+// OverlapSkip.
+func ParseOverlapPolicy(s string) (OverlapPolicy, error) {
+	if s == "" {
+		return OverlapSkip, nil
+	}
+	switch policy := OverlapPolicy(strings.ToLower(s)); policy {
+	case OverlapSkip, OverlapQueue, OverlapAllow:
+		return policy, nil
+	}
+	return "", fmt.Errorf("%w: unknown overlap policy %q, want skip, queue or allow", apperrors.ErrInvalidArgument, s)
+}
+
+// OverlapGuard keeps the runs of a batch job from overlapping by holding a
+// lock named after the job while one runs. The lock lasts a lease that the
+// guard extends for as long as the run goes, so a run whose instance died
+// frees the job once the lease lapses.
+type OverlapGuard struct {
+	locker Locker
+	lease  time.Duration
+	clock  clock.Clock
+	logger *slog.Logger
+}
+
+// NewOverlapGuard locks through locker for lease at a time; zero or less
+// means DefaultLease, the lease of the jobs that carry the runs. Times come
+// from clk; nil means the wall clock.
+func NewOverlapGuard(locker Locker, lease time.Duration, clk clock.Clock, logger *slog.Logger) *OverlapGuard {
+	if locker == nil || logger == nil {
+		panic("locker and logger cannot be nil")
+	}
+	if lease <= 0 {
+		lease = DefaultLease
+	}
+	return &OverlapGuard{locker: locker, lease: lease, clock: clock.OrSystem(clk), logger: logger.With("component", "OverlapGuard")}
+}
+
+// Run runs fn as a run of the job called name under policy. owner names the
+// run in the lock; a job taken over after its worker stopped keeps its ID
+// and with it the lock. While another owner holds the job, OverlapSkip
+// returns ErrRunOverlapped without running fn, and OverlapQueue waits up to
+// wait for the lock before doing the same. OverlapAllow, or a nil guard,
+// runs fn straight away.
+func (g *OverlapGuard) Run(ctx context.Context, name, owner string, policy OverlapPolicy, wait time.Duration, fn func(context.Context) error) error {
+	if g == nil || policy == OverlapAllow {
+		return fn(ctx)
+	}
+	acquired, err := g.tryLock(ctx, name, owner)
+	if err == nil && !acquired && policy == OverlapQueue {
+		acquired, err = g.waitLock(ctx, name, owner, wait)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock batch job %s: %w", name, err)
+	}
+	if !acquired {
+		return fmt.Errorf("batch job %s: %w", name, ErrRunOverlapped)
+	}
+
+	held, release := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		g.hold(held, name, owner)
+	}()
+	defer func() {
+		release()
+		wg.Wait()
+		if err := g.locker.Unlock(context.WithoutCancel(ctx), name, owner); err != nil {
+			g.logger.Warn("Failed to unlock batch job, it stays locked until the lease lapses",
+				slog.String("job_name", name), slog.Any("error", err))
+		}
+	}()
+	return fn(ctx)
+}
+
+func (g *OverlapGuard) tryLock(ctx context.Context, name, owner string) (bool, error) {
+	now := g.clock.Now().UTC()
+	return g.locker.TryLock(ctx, name, owner, now, now.Add(g.lease))
+}
+
+// waitLock tries the lock again every third of the lease until it is taken,
+// wait has passed or ctx ends.
+func (g *OverlapGuard) waitLock(ctx context.Context, name, owner string, wait time.Duration) (bool, error) {
+	g.logger.Info("Batch job run waiting for the previous run to end", slog.String("job_name", name), slog.Duration("wait", wait))
+	deadline := time.NewTimer(wait)
+	defer deadline.Stop()
+	ticker := time.NewTicker(g.lease / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-deadline.C:
+			return false, nil
+		case <-ticker.C:
+			acquired, err := g.tryLock(ctx, name, owner)
+			if err != nil || acquired {
+				return acquired, err
+			}
+		}
+	}
+}
+
+// hold extends the lock every third of the lease until ctx ends.
+func (g *OverlapGuard) hold(ctx context.Context, name, owner string) {
+	ticker := time.NewTicker(g.lease / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			acquired, err := g.tryLock(ctx, name, owner)
+			switch {
+			case err != nil:
+				if ctx.Err() == nil {
+					g.logger.Warn("Failed to extend batch job lock", slog.String("job_name", name), slog.Any("error", err))
+				}
+			case !acquired:
+				g.logger.Error("Batch job lock lapsed and was taken by another run", slog.String("job_name", name))
+			}
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package jobs
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOverlapGuard(t *testing.T) {
+	ctx := context.Background()
+	const lease = 30 * time.Millisecond
+	lockedBy := func(t *testing.T, locker *MemoryLocker, owner string, expires time.Time) {
+		t.Helper()
+		acquired, err := locker.TryLock(ctx, "DelinquencyUpdate", owner, time.Now(), expires)
+		require.NoError(t, err)
+		require.True(t, acquired)
+	}
+	free := func(locker *MemoryLocker) bool {
+		acquired, _ := locker.TryLock(ctx, "DelinquencyUpdate", "probe", time.Now(), time.Now())
+		return acquired
+	}
+
+	t.Run("skips a run while another holds the job", func(t *testing.T) {
+		locker := NewMemoryLocker()
+		guard := NewOverlapGuard(locker, lease, nil, testLogger)
+		lockedBy(t, locker, "job-1", time.Now().Add(time.Hour))
+
+		ran := false
+		err := guard.Run(ctx, "DelinquencyUpdate", "job-2", OverlapSkip, time.Hour, func(context.Context) error {
+			ran = true
+			return nil
+		})
+
+		assert.ErrorIs(t, err, ErrRunOverlapped)
+		assert.False(t, ran)
+	})
+
+	t.Run("holds the job for the length of the run", func(t *testing.T) {
+		locker := NewMemoryLocker()
+		guard := NewOverlapGuard(locker, lease, nil, testLogger)
+
+		err := guard.Run(ctx, "DelinquencyUpdate", "job-1", OverlapSkip, 0, func(context.Context) error {
+			time.Sleep(3 * lease)
+			assert.False(t, free(locker), "the lock is extended past its first lease")
+			return nil
+		})
+
+		require.NoError(t, err)
+		assert.True(t, free(locker), "released after the run")
+	})
+
+	t.Run("takes over a lapsed lock and one it holds already", func(t *testing.T) {
+		locker := NewMemoryLocker()
+		guard := NewOverlapGuard(locker, lease, nil, testLogger)
+		noop := func(context.Context) error { return nil }
+
+		lockedBy(t, locker, "job-1", time.Now().Add(-time.Second))
+		assert.NoError(t, guard.Run(ctx, "DelinquencyUpdate", "job-2", OverlapSkip, 0, noop))
+
+		lockedBy(t, locker, "job-3", time.Now().Add(time.Hour))
+		assert.NoError(t, guard.Run(ctx, "DelinquencyUpdate", "job-3", OverlapSkip, 0, noop), "a job run again keeps its lock")
+	})
+
+	t.Run("queues a run until the previous one ends", func(t *testing.T) {
+		locker := NewMemoryLocker()
+		guard := NewOverlapGuard(locker, lease, nil, testLogger)
+		lockedBy(t, locker, "job-1", time.Now().Add(time.Hour))
+		go func() {
+			time.Sleep(2 * lease)
+			_ = locker.Unlock(ctx, "DelinquencyUpdate", "job-1")
+		}()
+
+		ran := false
+		err := guard.Run(ctx, "DelinquencyUpdate", "job-2", OverlapQueue, time.Second, func(context.Context) error {
+			ran = true
+			return nil
+		})
+
+		require.NoError(t, err)
+		assert.True(t, ran)
+	})
+
+	t.Run("a queued run gives up after the wait", func(t *testing.T) {
+		locker := NewMemoryLocker()
+		guard := NewOverlapGuard(locker, lease, nil, testLogger)
+		lockedBy(t, locker, "job-1", time.Now().Add(time.Hour))
+
+		err := guard.Run(ctx, "DelinquencyUpdate", "job-2", OverlapQueue, 2*lease, func(context.Context) error {
+			t.Fatal("ran while the previous run held the job")
+			return nil
+		})
+
+		assert.ErrorIs(t, err, ErrRunOverlapped)
+	})
+
+	t.Run("allow runs regardless", func(t *testing.T) {
+		locker := NewMemoryLocker()
+		lockedBy(t, locker, "job-1", time.Now().Add(time.Hour))
+		noop := func(context.Context) error { return nil }
+
+		assert.NoError(t, NewOverlapGuard(locker, lease, nil, testLogger).Run(ctx, "DelinquencyUpdate", "job-2", OverlapAllow, 0, noop))
+		assert.NoError(t, (*OverlapGuard)(nil).Run(ctx, "DelinquencyUpdate", "job-2", OverlapSkip, 0, noop))
+	})
+}
+
+func TestParseOverlapPolicy(t *testing.T) {
+	policy, err := ParseOverlapPolicy("Queue")
+	require.NoError(t, err)
+	assert.Equal(t, OverlapQueue, policy)
+
+	_, err = ParseOverlapPolicy("twice")
+	assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+}
@@ -0,0 +1,248 @@
+package jobs
+
+import (
+	"billing-engine/internal/infrastructure/monitoring"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// MaxErrorSamples is how many item errors a run keeps; the rest are only
+	// counted.
+	MaxErrorSamples = 5
+	maxSampleLength = 500
+
+	// runFlushInterval is how often the progress of a running batch job is
+	// written to the run store.
+	runFlushInterval = 5 * time.Second
+)
+
+// ErrRunOverran is the cause of a run the watchdog cancelled for running
+// past its maximum runtime.
+var ErrRunOverran = errors.New("cancelled after running past its maximum runtime")
+
+// Progress collects how far a batch job run has got. The job reports
+// through the Progress in its context; all methods do nothing on a nil
+// Progress, so jobs run outside a recorder need no checks.
+type Progress struct {
+	mu        sync.Mutex
+	total     int
+	processed int
+	failed    int
+	samples   []string
+}
+
+type progressKey struct{}
+
+// WithProgress returns ctx carrying p, for the job to report to.
+func WithProgress(ctx context.Context, p *Progress) context.Context {
+	return context.WithValue(ctx, progressKey{}, p)
+}
+
+// ProgressFrom returns the Progress of the run ctx belongs to, nil if none.
+func ProgressFrom(ctx context.Context) *Progress {
+	p, _ := ctx.Value(progressKey{}).(*Progress)
+	return p
+}
+
+// SetTotal sets how many items the run works through.
+func (p *Progress) SetTotal(total int) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	p.total = total
+	p.mu.Unlock()
+}
+
+// Done counts n items processed without error.
+func (p *Progress) Done(n int) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	p.processed += n
+	p.mu.Unlock()
+}
+
+// Fail counts one item processed with err, keeping its message while fewer
+// than MaxErrorSamples are kept.
+func (p *Progress) Fail(err error) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.processed++
+	p.failed++
+	if len(p.samples) < MaxErrorSamples {
+		msg := err.Error()
+		if len(msg) > maxSampleLength {
+			msg = msg[:maxSampleLength]
+		}
+		p.samples = append(p.samples, msg)
+	}
+}
+
+// copyTo writes the counts and samples into run.
+func (p *Progress) copyTo(run *Run) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	run.Total, run.Processed, run.Failed = p.total, p.processed, p.failed
+	run.ErrorSamples = slices.Clone(p.samples)
+}
+
+// RunRecorder records the runs of the scheduled batch jobs in a RunStore and
+// publishes their progress as metrics.
+type RunRecorder struct {
+	store  RunStore
+	clock  clock.Clock
+	logger *slog.Logger
+
+	mu    sync.RWMutex
+	names map[string]bool
+	// active cancels the runs in progress on this instance, by run ID.
+	active map[int64]context.CancelCauseFunc
+}
+
+// NewRunRecorder returns a recorder writing to store. Run times come from
+// clk; nil means the wall clock, which is what production wants even when
+// billing runs on a sandbox clock.
+func NewRunRecorder(store RunStore, clk clock.Clock, logger *slog.Logger) *RunRecorder {
+	if store == nil {
+		panic("run store cannot be nil")
+	}
+	if logger == nil {
+		panic("logger cannot be nil")
+	}
+	return &RunRecorder{store: store, clock: clock.OrSystem(clk), logger: logger, names: make(map[string]bool), active: make(map[int64]context.CancelCauseFunc)}
+}
+
+// Register makes the runs of the job called name readable through List and
+// Get, even before its first run.
+func (r *RunRecorder) Register(name string) {
+	r.mu.Lock()
+	r.names[name] = true
+	r.mu.Unlock()
+}
+
+// Track runs fn as a run of the job called name, carried by the async job
+// jobID, and records it. fn reports its progress through ProgressFrom. A
+// run that cannot be recorded still runs: losing the record is better than
+// skipping the night's work.
+func (r *RunRecorder) Track(ctx context.Context, name, jobID string, fn func(context.Context) error) error {
+	r.Register(name)
+	logger := r.logger.With(slog.String("job_name", name), slog.String("job_id", jobID))
+	now := r.clock.Now().UTC()
+	run := &Run{Name: name, JobID: jobID, Status: StatusRunning, StartedAt: now, UpdatedAt: now, ErrorSamples: []string{}}
+	recorded := true
+	if err := r.store.StartRun(ctx, run); err != nil {
+		logger.Error("Failed to record the start of a batch job run", slog.Any("error", err))
+		recorded = false
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	if recorded {
+		r.mu.Lock()
+		r.active[run.ID] = cancel
+		r.mu.Unlock()
+		defer func() {
+			r.mu.Lock()
+			delete(r.active, run.ID)
+			r.mu.Unlock()
+		}()
+	}
+
+	progress := &Progress{}
+	monitoring.SetBatchJobProgress(name, true, 0, 0)
+	stop := make(chan struct{})
+	flushed := make(chan struct{})
+	go func() {
+		defer close(flushed)
+		ticker := time.NewTicker(runFlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				progress.copyTo(run)
+				monitoring.SetBatchJobProgress(name, true, run.Processed, run.Total)
+				if !recorded {
+					continue
+				}
+				run.UpdatedAt = r.clock.Now().UTC()
+				if err := r.store.UpdateRun(ctx, run); err != nil {
+					logger.Warn("Failed to record batch job progress", slog.Any("error", err))
+				}
+			}
+		}
+	}()
+
+	runErr := fn(WithProgress(ctx, progress))
+	close(stop)
+	<-flushed
+	if runErr != nil && errors.Is(context.Cause(ctx), ErrRunOverran) {
+		runErr = fmt.Errorf("%w: %w", ErrRunOverran, runErr)
+	}
+
+	progress.copyTo(run)
+	monitoring.SetBatchJobProgress(name, false, run.Processed, run.Total)
+	finishedAt := r.clock.Now().UTC()
+	run.UpdatedAt, run.FinishedAt, run.Status = finishedAt, &finishedAt, StatusSucceeded
+	if runErr != nil {
+		run.Status, run.Error = StatusFailed, runErr.Error()
+	}
+	if recorded {
+		// The run's context may be past its deadline by now.
+		if err := r.store.FinishRun(context.WithoutCancel(ctx), run); err != nil {
+			logger.Error("Failed to record the end of a batch job run", slog.Any("error", err))
+		}
+	}
+	return runErr
+}
+
+// Cancel cancels the context of run id if it is in progress on this
+// instance and reports whether it was. The job stops once it notices.
+func (r *RunRecorder) Cancel(id int64, cause error) bool {
+	r.mu.RLock()
+	cancel, ok := r.active[id]
+	r.mu.RUnlock()
+	if ok {
+		cancel(cause)
+	}
+	return ok
+}
+
+// List returns the latest runs of the job called name, newest first.
+func (r *RunRecorder) List(ctx context.Context, name string, limit int) ([]Run, error) {
+	if err := r.known(name); err != nil {
+		return nil, err
+	}
+	return r.store.ListRuns(ctx, name, limit)
+}
+
+// Get returns run id of the job called name.
+func (r *RunRecorder) Get(ctx context.Context, name string, id int64) (*Run, error) {
+	if err := r.known(name); err != nil {
+		return nil, err
+	}
+	return r.store.GetRun(ctx, name, id)
+}
+
+func (r *RunRecorder) known(name string) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if !r.names[name] {
+		return fmt.Errorf("%w: no batch job called %s", apperrors.ErrNotFound, name)
+	}
+	return nil
+}
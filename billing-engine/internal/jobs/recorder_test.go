@@ -0,0 +1,146 @@
+package jobs
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunRecorder(t *testing.T) {
+	ctx := context.Background()
+	started := time.Date(2025, 3, 3, 2, 0, 0, 0, time.UTC)
+
+	t.Run("records the counts and error samples of a run", func(t *testing.T) {
+		clk := clock.NewFake(started)
+		store := NewMemoryRunStore()
+		recorder := NewRunRecorder(store, clk, testLogger)
+
+		err := recorder.Track(ctx, "DelinquencyUpdate", "job-1", func(ctx context.Context) error {
+			progress := ProgressFrom(ctx)
+			progress.SetTotal(10)
+			progress.Done(2)
+			for i := 0; i < MaxErrorSamples+2; i++ {
+				progress.Fail(errors.New(strings.Repeat("x", maxSampleLength+1)))
+			}
+			clk.Advance(90 * time.Second)
+			return nil
+		})
+		require.NoError(t, err)
+
+		runs, err := recorder.List(ctx, "DelinquencyUpdate", 10)
+		require.NoError(t, err)
+		require.Len(t, runs, 1)
+		run := runs[0]
+		assert.Equal(t, StatusSucceeded, run.Status)
+		assert.Equal(t, "job-1", run.JobID)
+		assert.Equal(t, 10, run.Total)
+		assert.Equal(t, 2+MaxErrorSamples+2, run.Processed, "failed items count as processed")
+		assert.Equal(t, MaxErrorSamples+2, run.Failed)
+		require.Len(t, run.ErrorSamples, MaxErrorSamples)
+		assert.Len(t, run.ErrorSamples[0], maxSampleLength)
+		assert.Equal(t, 90*time.Second, run.Duration(clk.Now()))
+
+		got, err := recorder.Get(ctx, "DelinquencyUpdate", run.ID)
+		require.NoError(t, err)
+		assert.Equal(t, run.ID, got.ID)
+	})
+
+	t.Run("keeps the error of a failed run", func(t *testing.T) {
+		recorder := NewRunRecorder(NewMemoryRunStore(), clock.NewFake(started), testLogger)
+
+		err := recorder.Track(ctx, "IntegrityCheck", "job-2", func(context.Context) error {
+			return errors.New("connection refused")
+		})
+		assert.EqualError(t, err, "connection refused")
+
+		runs, err := recorder.List(ctx, "IntegrityCheck", 10)
+		require.NoError(t, err)
+		require.Len(t, runs, 1)
+		assert.Equal(t, StatusFailed, runs[0].Status)
+		assert.Equal(t, "connection refused", runs[0].Error)
+		assert.NotNil(t, runs[0].FinishedAt)
+	})
+
+	t.Run("still runs the job when the run cannot be recorded", func(t *testing.T) {
+		recorder := NewRunRecorder(failingRunStore{}, nil, testLogger)
+		ran := false
+
+		err := recorder.Track(ctx, "LoanSnapshot", "job-3", func(context.Context) error {
+			ran = true
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.True(t, ran)
+	})
+
+	t.Run("unknown jobs are not found", func(t *testing.T) {
+		recorder := NewRunRecorder(NewMemoryRunStore(), nil, testLogger)
+		recorder.Register("SummaryRebuild")
+
+		runs, err := recorder.List(ctx, "SummaryRebuild", 10)
+		require.NoError(t, err)
+		assert.Empty(t, runs, "a registered job without runs lists none")
+
+		_, err = recorder.List(ctx, "Nope", 10)
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+		_, err = recorder.Get(ctx, "SummaryRebuild", 1)
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+
+	t.Run("jobs run outside a recorder report to nothing", func(t *testing.T) {
+		progress := ProgressFrom(ctx)
+		assert.Nil(t, progress)
+		progress.SetTotal(1)
+		progress.Done(1)
+		progress.Fail(errors.New("ignored"))
+	})
+}
+
+func TestMemoryRunStore(t *testing.T) {
+	ctx := context.Background()
+	at := time.Date(2025, 3, 3, 2, 0, 0, 0, time.UTC)
+	store := NewMemoryRunStore()
+
+	first := &Run{Name: "DelinquencyUpdate", JobID: "job-1", Status: StatusRunning, StartedAt: at}
+	require.NoError(t, store.StartRun(ctx, first))
+	other := &Run{Name: "LoanSnapshot", JobID: "job-2", Status: StatusRunning, StartedAt: at}
+	require.NoError(t, store.StartRun(ctx, other))
+
+	retry := &Run{Name: "DelinquencyUpdate", JobID: "job-1", Status: StatusRunning, StartedAt: at.Add(time.Minute)}
+	require.NoError(t, store.StartRun(ctx, retry))
+
+	abandoned, err := store.GetRun(ctx, "DelinquencyUpdate", first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusFailed, abandoned.Status, "the run of a job claimed again is closed")
+	assert.Equal(t, AbandonedRunError, abandoned.Error)
+	stillRunning, err := store.GetRun(ctx, "LoanSnapshot", other.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusRunning, stillRunning.Status)
+
+	retry.Processed = 4
+	require.NoError(t, store.UpdateRun(ctx, retry))
+	runs, err := store.ListRuns(ctx, "DelinquencyUpdate", 1)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, retry.ID, runs[0].ID, "newest first")
+	assert.Equal(t, 4, runs[0].Processed)
+
+	_, err = store.GetRun(ctx, "LoanSnapshot", first.ID)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound, "a run is only found under its own job")
+}
+
+type failingRunStore struct {
+	RunStore
+}
+
+func (failingRunStore) StartRun(context.Context, *Run) error {
+	return errors.New("database is down")
+}
@@ -0,0 +1,489 @@
+package jobs
+
+import (
+	"billing-engine/internal/infrastructure/monitoring"
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultWorkers      = 2
+	DefaultQueueSize    = 16
+	DefaultRetention    = time.Hour
+	DefaultPollInterval = 2 * time.Second
+	DefaultLease        = 2 * time.Minute
+	DefaultMaxAttempts  = 3
+
+	// progressInterval is how often at most the progress of a job is
+	// written to the store.
+	progressInterval = time.Second
+)
+
+// ErrQueueFull is returned by Submit while the queue holds QueueSize jobs.
+// Callers should ask the client to retry later.
+var ErrQueueFull = fmt.Errorf("%w: too many jobs waiting, retry later", apperrors.ErrUnavailable)
+
+type Status string
+
+const (
+	StatusQueued    Status = "QUEUED"
+	StatusRunning   Status = "RUNNING"
+	StatusSucceeded Status = "SUCCEEDED"
+	StatusFailed    Status = "FAILED"
+)
+
+// Job is a snapshot of an async job. Total counts the items the job works
+// through, zero when unknown, and Processed those done so far. Result holds
+// the encoded result once the job succeeded, Error the message once it
+// failed. Attempts counts the claims, so it is above one when a worker died
+// or shut down while running the job.
+type Job struct {
+	ID          string
+	Kind        string
+	Status      Status
+	DedupeKey   string
+	Payload     json.RawMessage
+	Result      json.RawMessage
+	Error       string
+	Total       int
+	Processed   int
+	Attempts    int
+	Worker      string
+	CreatedAt   time.Time
+	StartedAt   *time.Time
+	HeartbeatAt *time.Time
+	FinishedAt  *time.Time
+}
+
+// DecodePayload decodes the payload the job was submitted with into v.
+func (j Job) DecodePayload(v any) error {
+	if err := json.Unmarshal(j.Payload, v); err != nil {
+		return fmt.Errorf("%w: job %s has an unreadable payload: %w", apperrors.ErrInternalServer, j.ID, err)
+	}
+	return nil
+}
+
+// Handler does the work of a job and returns its result, which is stored as
+// JSON. It reports how many items it has processed through progress.
+type Handler func(ctx context.Context, job Job, progress func(processed int)) (any, error)
+
+// Spec describes a job to submit. Payload is stored as JSON and handed to
+// the handler of Kind, which may run on another instance.
+type Spec struct {
+	Kind    string
+	Total   int
+	Payload any
+	// DedupeKey makes Submit fail with ErrAlreadyExists while the store keeps
+	// a job with the same key.
+	DedupeKey string
+	// Scheduled jobs skip the queue limit: a nightly run must not be dropped
+	// because uploads are waiting.
+	Scheduled bool
+}
+
+// Config sizes a Runner. Zero fields take the defaults.
+type Config struct {
+	Workers   int
+	QueueSize int
+	// Retention is how long finished jobs can still be polled.
+	Retention    time.Duration
+	PollInterval time.Duration
+	// Lease is how long a running job may go without a heartbeat before
+	// another worker takes it over.
+	Lease       time.Duration
+	MaxAttempts int
+	// Worker names this instance in the jobs it claims; host name and
+	// process ID when empty.
+	Worker string
+}
+
+func (c Config) withDefaults() Config {
+	if c.Workers <= 0 {
+		c.Workers = DefaultWorkers
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = DefaultQueueSize
+	}
+	if c.Retention <= 0 {
+		c.Retention = DefaultRetention
+	}
+	if c.PollInterval <= 0 {
+		c.PollInterval = DefaultPollInterval
+	}
+	if c.Lease <= 0 {
+		c.Lease = DefaultLease
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = DefaultMaxAttempts
+	}
+	if c.Worker == "" {
+		host, _ := os.Hostname()
+		c.Worker = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+	return c
+}
+
+// Runner runs the jobs of a Store on a fixed number of workers. Instances
+// that share a store share its queue: a job submitted on one may run on
+// another, and any of them answers for its status. Workers only claim the
+// kinds registered on their runner.
+type Runner struct {
+	store  Store
+	cfg    Config
+	clock  clock.Clock
+	logger *slog.Logger
+	wake   chan struct{}
+
+	mu       sync.Mutex
+	handlers map[string]Handler
+	running  map[string]bool
+	closed   bool
+
+	stopping   chan struct{}
+	wg         sync.WaitGroup
+	maintained chan struct{}
+	done       context.Context
+	cancel     context.CancelFunc
+}
+
+// NewRunner builds a runner on store. clk stamps the jobs and times their
+// leases; nil means the wall clock, which is what production wants even when
+// billing runs on a sandbox clock.
+func NewRunner(store Store, cfg Config, clk clock.Clock, logger *slog.Logger) *Runner {
+	if store == nil || logger == nil {
+		panic("job store and logger cannot be nil")
+	}
+	cfg = cfg.withDefaults()
+	return &Runner{
+		store:    store,
+		cfg:      cfg,
+		clock:    clock.OrSystem(clk),
+		logger:   logger.With("component", "JobRunner"),
+		wake:     make(chan struct{}, cfg.Workers),
+		handlers: map[string]Handler{},
+		running:  map[string]bool{},
+		stopping: make(chan struct{}),
+	}
+}
+
+// Register runs h for the jobs of kind. Register every kind before Start so
+// that the first claims already take them.
+func (r *Runner) Register(kind string, h Handler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.handlers[kind]; ok {
+		panic(fmt.Sprintf("job handler for %q registered twice", kind))
+	}
+	r.handlers[kind] = h
+}
+
+// Start launches the workers, which poll the store every PollInterval and
+// straight away after a local Submit. Jobs run on a context that ends when
+// ctx does or Stop gives up waiting.
+func (r *Runner) Start(ctx context.Context) {
+	r.done, r.cancel = context.WithCancel(ctx)
+	for range r.cfg.Workers {
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			r.work()
+		}()
+	}
+	r.maintained = make(chan struct{})
+	go func() {
+		defer close(r.maintained)
+		r.maintain()
+	}()
+	r.logger.Info("Job runner started", slog.String("worker", r.cfg.Worker), slog.Any("kinds", r.kinds()))
+}
+
+// Stop refuses new jobs and waits for the running ones to finish. When ctx
+// ends first their contexts are cancelled and they go back to the queue for
+// another instance, or the next start, to run again.
+func (r *Runner) Stop(ctx context.Context) error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	close(r.stopping)
+	r.mu.Unlock()
+	if r.cancel == nil {
+		return nil
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(finished)
+	}()
+	var err error
+	select {
+	case <-finished:
+	case <-ctx.Done():
+		r.cancel()
+		<-finished
+		err = ctx.Err()
+	}
+	r.cancel()
+	<-r.maintained
+	return err
+}
+
+// Submit queues a job and returns it in the QUEUED state. It fails with
+// ErrQueueFull instead of waiting when QueueSize jobs are queued; instances
+// that submit at the same time may overshoot the limit by a few.
+func (r *Runner) Submit(ctx context.Context, spec Spec) (Job, error) {
+	r.mu.Lock()
+	closed := r.closed
+	_, known := r.handlers[spec.Kind]
+	r.mu.Unlock()
+	if closed {
+		return Job{}, fmt.Errorf("%w: shutting down", apperrors.ErrUnavailable)
+	}
+	if !known {
+		return Job{}, fmt.Errorf("%w: no handler for job kind %q", apperrors.ErrInternalServer, spec.Kind)
+	}
+
+	if !spec.Scheduled {
+		queued, err := r.store.CountQueued(ctx)
+		if err != nil {
+			return Job{}, err
+		}
+		monitoring.SetAsyncJobsQueued(queued)
+		if queued >= r.cfg.QueueSize {
+			monitoring.RecordAsyncJobRejected(spec.Kind)
+			r.logger.WarnContext(ctx, "Job queue is full", slog.String("kind", spec.Kind), slog.Int("queued", queued))
+			return Job{}, ErrQueueFull
+		}
+	}
+
+	job := Job{
+		ID: uuid.NewString(), Kind: spec.Kind, Status: StatusQueued, DedupeKey: spec.DedupeKey,
+		Total: spec.Total, CreatedAt: r.clock.Now().UTC(),
+	}
+	if spec.Payload != nil {
+		payload, err := json.Marshal(spec.Payload)
+		if err != nil {
+			return Job{}, fmt.Errorf("%w: failed to encode %s job: %w", apperrors.ErrInternalServer, spec.Kind, err)
+		}
+		job.Payload = payload
+	}
+	if err := r.store.Create(ctx, &job); err != nil {
+		return Job{}, err
+	}
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+	r.logger.InfoContext(ctx, "Job queued", slog.String("job_id", job.ID), slog.String("kind", job.Kind), slog.Int("total", job.Total))
+	return job, nil
+}
+
+// Get returns the current state of a job, or ErrNotFound once it finished
+// more than the retention ago.
+func (r *Runner) Get(ctx context.Context, id string) (Job, error) {
+	job, err := r.store.Get(ctx, id)
+	if err != nil {
+		return Job{}, err
+	}
+	if r.expired(job) {
+		return Job{}, fmt.Errorf("%w: job %s", apperrors.ErrNotFound, id)
+	}
+	return *job, nil
+}
+
+// List returns the jobs still kept that match f, newest first.
+func (r *Runner) List(ctx context.Context, f Filter) ([]Job, error) {
+	found, err := r.store.List(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(found, func(job Job) bool { return r.expired(&job) }), nil
+}
+
+func (r *Runner) expired(job *Job) bool {
+	return job.FinishedAt != nil && job.FinishedAt.Before(r.clock.Now().Add(-r.cfg.Retention))
+}
+
+func (r *Runner) kinds() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	kinds := make([]string, 0, len(r.handlers))
+	for kind := range r.handlers {
+		kinds = append(kinds, kind)
+	}
+	slices.Sort(kinds)
+	return kinds
+}
+
+func (r *Runner) work() {
+	for {
+		select {
+		case <-r.stopping:
+			return
+		default:
+		}
+		if job := r.claim(); job != nil {
+			r.run(*job)
+			continue
+		}
+		select {
+		case <-r.stopping:
+			return
+		case <-r.wake:
+		case <-time.After(r.cfg.PollInterval):
+		}
+	}
+}
+
+func (r *Runner) claim() *Job {
+	kinds := r.kinds()
+	if len(kinds) == 0 {
+		return nil
+	}
+	now := r.clock.Now().UTC()
+	job, err := r.store.Claim(r.done, kinds, r.cfg.Worker, now, now.Add(-r.cfg.Lease))
+	if err != nil {
+		if r.done.Err() == nil {
+			r.logger.Error("Failed to claim a job", slog.Any("error", err))
+		}
+		return nil
+	}
+	return job
+}
+
+func (r *Runner) run(job Job) {
+	logger := r.logger.With(slog.String("job_id", job.ID), slog.String("kind", job.Kind))
+	if job.Attempts > r.cfg.MaxAttempts {
+		r.finish(logger, job, nil, fmt.Errorf("abandoned after %d attempts", job.Attempts-1))
+		return
+	}
+	if job.Attempts > 1 {
+		logger.Warn("Running a job again after its worker stopped", slog.Int("attempt", job.Attempts))
+	}
+
+	r.mu.Lock()
+	handler := r.handlers[job.Kind]
+	r.running[job.ID] = true
+	monitoring.SetAsyncJobsRunning(len(r.running))
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		delete(r.running, job.ID)
+		monitoring.SetAsyncJobsRunning(len(r.running))
+		r.mu.Unlock()
+	}()
+
+	result, err := r.execute(logger, job, handler)
+	if err != nil && r.done.Err() != nil {
+		if err := r.store.Requeue(context.Background(), job.ID); err != nil {
+			logger.Error("Failed to requeue a job cancelled by shutdown", slog.Any("error", err))
+			return
+		}
+		logger.Warn("Job cancelled by shutdown, queued again")
+		return
+	}
+	r.finish(logger, job, result, err)
+}
+
+// execute runs the handler, turning a panic into a failed job so that one bad
+// payload cannot take a worker down.
+func (r *Runner) execute(logger *slog.Logger, job Job, handler Handler) (result any, err error) {
+	ctx, cancel := context.WithCancel(r.done)
+	defer cancel()
+	defer func() {
+		if p := recover(); p != nil {
+			logger.Error("Job panicked", slog.Any("panic", p))
+			err = errors.New("job failed unexpectedly")
+		}
+	}()
+
+	var reported time.Time
+	return handler(ctx, job, func(processed int) {
+		if time.Since(reported) < progressInterval {
+			return
+		}
+		reported = time.Now()
+		if job.Total > 0 {
+			processed = min(processed, job.Total)
+		}
+		if err := r.store.Progress(ctx, job.ID, processed, r.clock.Now().UTC()); err != nil {
+			logger.Warn("Failed to record job progress", slog.Any("error", err))
+		}
+	})
+}
+
+func (r *Runner) finish(logger *slog.Logger, job Job, result any, err error) {
+	if err == nil && result != nil {
+		job.Result, err = json.Marshal(result)
+		if err != nil {
+			err = fmt.Errorf("failed to encode the result: %w", err)
+		}
+	}
+	now := r.clock.Now().UTC()
+	job.FinishedAt = &now
+	if err != nil {
+		job.Status, job.Error, job.Result = StatusFailed, err.Error(), nil
+	} else {
+		job.Status, job.Processed = StatusSucceeded, job.Total
+	}
+	if err := r.store.Finish(context.Background(), &job); err != nil {
+		logger.Error("Failed to record the end of a job", slog.Any("error", err))
+		return
+	}
+	monitoring.RecordAsyncJobFinished(job.Kind, string(job.Status))
+	attrs := []any{slog.String("status", string(job.Status)), slog.String("error", job.Error)}
+	if job.StartedAt != nil {
+		attrs = append(attrs, slog.Duration("duration", now.Sub(*job.StartedAt)))
+	}
+	logger.Info("Job finished", attrs...)
+}
+
+// maintain keeps the leases of the running jobs, forgets the jobs past the
+// retention and publishes the queue depth, until the runner is done.
+func (r *Runner) maintain() {
+	ticker := time.NewTicker(min(r.cfg.Lease/3, time.Minute))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.done.Done():
+			return
+		case <-ticker.C:
+		}
+
+		now := r.clock.Now().UTC()
+		r.mu.Lock()
+		running := make([]string, 0, len(r.running))
+		for id := range r.running {
+			running = append(running, id)
+		}
+		r.mu.Unlock()
+		for _, id := range running {
+			if err := r.store.Heartbeat(r.done, id, now); err != nil && r.done.Err() == nil {
+				r.logger.Warn("Failed to renew a job lease", slog.String("job_id", id), slog.Any("error", err))
+			}
+		}
+
+		if n, err := r.store.DeleteFinishedBefore(r.done, now.Add(-r.cfg.Retention)); err != nil && r.done.Err() == nil {
+			r.logger.Warn("Failed to delete expired jobs", slog.Any("error", err))
+		} else if n > 0 {
+			r.logger.Debug("Deleted expired jobs", slog.Int64("count", n))
+		}
+		if queued, err := r.store.CountQueued(r.done); err == nil {
+			monitoring.SetAsyncJobsQueued(queued)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package jobs
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func newTestRunner(t *testing.T, store Store, cfg Config, clk clock.Clock, handlers map[string]Handler) *Runner {
+	t.Helper()
+	cfg.PollInterval = 5 * time.Millisecond
+	r := NewRunner(store, cfg, clk, testLogger)
+	for kind, h := range handlers {
+		r.Register(kind, h)
+	}
+	r.Start(context.Background())
+	t.Cleanup(func() { r.Stop(context.Background()) })
+	return r
+}
+
+// waitFor polls the job until it reaches status.
+func waitFor(t *testing.T, r *Runner, id string, status Status) Job {
+	t.Helper()
+	var job Job
+	require.Eventually(t, func() bool {
+		var err error
+		job, err = r.Get(context.Background(), id)
+		require.NoError(t, err)
+		return job.Status == status
+	}, time.Second, 5*time.Millisecond)
+	return job
+}
+
+func TestRunner(t *testing.T) {
+	t.Run("runs a job and keeps its result and progress", func(t *testing.T) {
+		release := make(chan struct{})
+		r := newTestRunner(t, NewMemoryStore(), Config{Workers: 1}, nil, map[string]Handler{
+			"import": func(ctx context.Context, job Job, progress func(int)) (any, error) {
+				var rows []string
+				if err := job.DecodePayload(&rows); err != nil {
+					return nil, err
+				}
+				progress(4)
+				<-release
+				return map[string]int{"rows": len(rows)}, nil
+			},
+		})
+
+		job, err := r.Submit(context.Background(), Spec{Kind: "import", Total: 10, Payload: []string{"a", "b"}})
+		require.NoError(t, err)
+		assert.Equal(t, StatusQueued, job.Status)
+		assert.NotEmpty(t, job.ID)
+
+		require.Eventually(t, func() bool {
+			running, err := r.Get(context.Background(), job.ID)
+			return err == nil && running.Status == StatusRunning && running.Processed == 4
+		}, time.Second, 5*time.Millisecond)
+		close(release)
+
+		finished := waitFor(t, r, job.ID, StatusSucceeded)
+		assert.JSONEq(t, `{"rows": 2}`, string(finished.Result))
+		assert.Equal(t, 10, finished.Processed)
+		assert.Equal(t, 1, finished.Attempts)
+		assert.Nil(t, finished.Payload)
+		assert.NotNil(t, finished.StartedAt)
+		assert.NotNil(t, finished.FinishedAt)
+	})
+
+	t.Run("records a failed or panicking job", func(t *testing.T) {
+		r := newTestRunner(t, NewMemoryStore(), Config{Workers: 1}, nil, map[string]Handler{
+			"fail":  func(context.Context, Job, func(int)) (any, error) { return nil, errors.New("bank file rejected") },
+			"panic": func(context.Context, Job, func(int)) (any, error) { panic("boom") },
+		})
+
+		failed, err := r.Submit(context.Background(), Spec{Kind: "fail"})
+		require.NoError(t, err)
+		panicked, err := r.Submit(context.Background(), Spec{Kind: "panic"})
+		require.NoError(t, err)
+
+		assert.Equal(t, "bank file rejected", waitFor(t, r, failed.ID, StatusFailed).Error)
+		assert.Equal(t, "job failed unexpectedly", waitFor(t, r, panicked.ID, StatusFailed).Error)
+	})
+
+	t.Run("refuses jobs once the queue is full, except scheduled ones", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+		r := newTestRunner(t, NewMemoryStore(), Config{Workers: 1, QueueSize: 1}, nil, map[string]Handler{
+			"import": func(context.Context, Job, func(int)) (any, error) {
+				<-release
+				return nil, nil
+			},
+		})
+
+		first, err := r.Submit(context.Background(), Spec{Kind: "import"})
+		require.NoError(t, err)
+		waitFor(t, r, first.ID, StatusRunning)
+		_, err = r.Submit(context.Background(), Spec{Kind: "import"})
+		require.NoError(t, err, "one job may wait")
+
+		_, err = r.Submit(context.Background(), Spec{Kind: "import"})
+		assert.ErrorIs(t, err, ErrQueueFull)
+		assert.ErrorIs(t, err, apperrors.ErrUnavailable)
+		_, err = r.Submit(context.Background(), Spec{Kind: "import", Scheduled: true})
+		assert.NoError(t, err)
+	})
+
+	t.Run("refuses a second job with the same dedupe key", func(t *testing.T) {
+		r := newTestRunner(t, NewMemoryStore(), Config{}, nil, map[string]Handler{
+			"batch": func(context.Context, Job, func(int)) (any, error) { return nil, nil },
+		})
+
+		_, err := r.Submit(context.Background(), Spec{Kind: "batch", DedupeKey: "batch@2025-01-06T02:00:00Z"})
+		require.NoError(t, err)
+		_, err = r.Submit(context.Background(), Spec{Kind: "batch", DedupeKey: "batch@2025-01-06T02:00:00Z"})
+
+		assert.ErrorIs(t, err, apperrors.ErrAlreadyExists)
+	})
+
+	t.Run("refuses a kind without a handler", func(t *testing.T) {
+		r := newTestRunner(t, NewMemoryStore(), Config{}, nil, nil)
+
+		_, err := r.Submit(context.Background(), Spec{Kind: "statement"})
+
+		assert.ErrorIs(t, err, apperrors.ErrInternalServer)
+	})
+
+	t.Run("leaves the kinds it has no handler for to other instances", func(t *testing.T) {
+		store := NewMemoryStore()
+		require.NoError(t, store.Create(context.Background(), &Job{ID: "other", Kind: "statement", Status: StatusQueued}))
+		r := newTestRunner(t, store, Config{}, nil, map[string]Handler{
+			"import": func(context.Context, Job, func(int)) (any, error) { return nil, nil },
+		})
+		job, err := r.Submit(context.Background(), Spec{Kind: "import"})
+		require.NoError(t, err)
+
+		waitFor(t, r, job.ID, StatusSucceeded)
+		other, err := r.Get(context.Background(), "other")
+		require.NoError(t, err)
+		assert.Equal(t, StatusQueued, other.Status)
+	})
+
+	t.Run("takes over a job whose worker stopped sending heartbeats", func(t *testing.T) {
+		clk := clock.NewFake(time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC))
+		store := NewMemoryStore()
+		require.NoError(t, store.Create(context.Background(), &Job{ID: "orphan", Kind: "import", Status: StatusQueued, CreatedAt: clk.Now()}))
+		_, err := store.Claim(context.Background(), []string{"import"}, "dead-worker", clk.Now(), clk.Now().Add(-time.Minute))
+		require.NoError(t, err)
+		clk.Advance(time.Minute + time.Second)
+
+		r := newTestRunner(t, store, Config{Lease: time.Minute}, clk, map[string]Handler{
+			"import": func(context.Context, Job, func(int)) (any, error) { return "done", nil },
+		})
+
+		job := waitFor(t, r, "orphan", StatusSucceeded)
+		assert.Equal(t, 2, job.Attempts)
+		assert.Equal(t, r.cfg.Worker, job.Worker)
+	})
+
+	t.Run("gives up on a job after the last attempt", func(t *testing.T) {
+		clk := clock.NewFake(time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC))
+		store := NewMemoryStore()
+		require.NoError(t, store.Create(context.Background(), &Job{ID: "poison", Kind: "import", Status: StatusQueued, CreatedAt: clk.Now()}))
+		for range 2 {
+			_, err := store.Claim(context.Background(), []string{"import"}, "dead-worker", clk.Now(), clk.Now())
+			require.NoError(t, err)
+			clk.Advance(2 * time.Minute)
+		}
+		ran := false
+
+		r := newTestRunner(t, store, Config{Lease: time.Minute, MaxAttempts: 2}, clk, map[string]Handler{
+			"import": func(context.Context, Job, func(int)) (any, error) {
+				ran = true
+				return nil, nil
+			},
+		})
+
+		assert.Equal(t, "abandoned after 2 attempts", waitFor(t, r, "poison", StatusFailed).Error)
+		assert.False(t, ran)
+	})
+
+	t.Run("forgets finished jobs after the retention", func(t *testing.T) {
+		clk := clock.NewFake(time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC))
+		r := newTestRunner(t, NewMemoryStore(), Config{Retention: time.Hour}, clk, map[string]Handler{
+			"import": func(context.Context, Job, func(int)) (any, error) { return nil, nil },
+		})
+
+		job, err := r.Submit(context.Background(), Spec{Kind: "import"})
+		require.NoError(t, err)
+		waitFor(t, r, job.ID, StatusSucceeded)
+
+		clk.Advance(59 * time.Minute)
+		_, err = r.Get(context.Background(), job.ID)
+		require.NoError(t, err)
+		clk.Advance(2 * time.Minute)
+		_, err = r.Get(context.Background(), job.ID)
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+		listed, err := r.List(context.Background(), Filter{})
+		require.NoError(t, err)
+		assert.Empty(t, listed)
+	})
+}
+
+func TestRunnerStop(t *testing.T) {
+	t.Run("waits for running jobs and refuses new ones", func(t *testing.T) {
+		finished := make(chan bool, 1)
+		r := NewRunner(NewMemoryStore(), Config{Workers: 1, PollInterval: 5 * time.Millisecond}, nil, testLogger)
+		r.Register("import", func(context.Context, Job, func(int)) (any, error) {
+			time.Sleep(20 * time.Millisecond)
+			finished <- true
+			return nil, nil
+		})
+		r.Start(context.Background())
+		job, err := r.Submit(context.Background(), Spec{Kind: "import"})
+		require.NoError(t, err)
+		waitFor(t, r, job.ID, StatusRunning)
+
+		require.NoError(t, r.Stop(context.Background()))
+
+		assert.Len(t, finished, 1)
+		stopped, err := r.Get(context.Background(), job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, StatusSucceeded, stopped.Status)
+		_, err = r.Submit(context.Background(), Spec{Kind: "import"})
+		assert.ErrorIs(t, err, apperrors.ErrUnavailable)
+	})
+
+	t.Run("queues jobs still running at the deadline again", func(t *testing.T) {
+		r := NewRunner(NewMemoryStore(), Config{Workers: 1, PollInterval: 5 * time.Millisecond}, nil, testLogger)
+		r.Register("import", func(ctx context.Context, _ Job, _ func(int)) (any, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+		r.Start(context.Background())
+		job, err := r.Submit(context.Background(), Spec{Kind: "import"})
+		require.NoError(t, err)
+		waitFor(t, r, job.ID, StatusRunning)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		assert.ErrorIs(t, r.Stop(ctx), context.DeadlineExceeded)
+
+		stopped, err := r.Get(context.Background(), job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, StatusQueued, stopped.Status)
+		assert.Nil(t, stopped.StartedAt)
+	})
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	at := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	for i, kind := range []string{"import", "replay", "import"} {
+		require.NoError(t, store.Create(ctx, &Job{ID: string(rune('a' + i)), Kind: kind, Status: StatusQueued, CreatedAt: at.Add(time.Duration(i) * time.Minute)}))
+	}
+
+	claimed, err := store.Claim(ctx, []string{"import"}, "w1", at, at)
+	require.NoError(t, err)
+	assert.Equal(t, "a", claimed.ID, "oldest first")
+	assert.Equal(t, StatusRunning, claimed.Status)
+	queued, err := store.CountQueued(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, queued)
+
+	finishedAt := at.Add(time.Minute)
+	claimed.Status, claimed.Result, claimed.FinishedAt = StatusSucceeded, json.RawMessage(`{"ok":true}`), &finishedAt
+	require.NoError(t, store.Finish(ctx, claimed))
+
+	listed, err := store.List(ctx, Filter{Kind: "import"})
+	require.NoError(t, err)
+	require.Len(t, listed, 2)
+	assert.Equal(t, "c", listed[0].ID, "newest first")
+	listed, err = store.List(ctx, Filter{Status: StatusSucceeded})
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.JSONEq(t, `{"ok":true}`, string(listed[0].Result))
+
+	deleted, err := store.DeleteFinishedBefore(ctx, finishedAt.Add(time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+	_, err = store.Get(ctx, "a")
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+}
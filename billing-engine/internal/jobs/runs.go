@@ -0,0 +1,136 @@
+package jobs
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Run is one execution of a scheduled batch job. Unlike the async job that
+// carried it, which is forgotten after the retention, a run is kept so that
+// outcomes can be looked up later. Total counts the items the run works
+// through, zero when the job does not say, Processed those done and Failed
+// those that went wrong; ErrorSamples holds the first of their messages.
+type Run struct {
+	ID           int64
+	Name         string
+	JobID        string
+	Status       Status
+	Total        int
+	Processed    int
+	Failed       int
+	ErrorSamples []string
+	Error        string
+	StartedAt    time.Time
+	UpdatedAt    time.Time
+	FinishedAt   *time.Time
+}
+
+// Duration is how long the run took, or has taken so far at now while it
+// is running.
+func (r Run) Duration(now time.Time) time.Duration {
+	if r.FinishedAt != nil {
+		return r.FinishedAt.Sub(r.StartedAt)
+	}
+	return now.Sub(r.StartedAt)
+}
+
+// RunStore keeps the runs of the batch jobs.
+type RunStore interface {
+	// StartRun stores run as running and sets its ID. Earlier runs of the
+	// same async job still marked running are closed as failed first: their
+	// worker stopped and the job was claimed again.
+	StartRun(ctx context.Context, run *Run) error
+	// UpdateRun stores the counts and error samples of a running run.
+	UpdateRun(ctx context.Context, run *Run) error
+	// FinishRun stores the final status, counts and error of run.
+	FinishRun(ctx context.Context, run *Run) error
+	GetRun(ctx context.Context, name string, id int64) (*Run, error)
+	// ListRuns returns the latest runs of the job called name, newest first.
+	ListRuns(ctx context.Context, name string, limit int) ([]Run, error)
+}
+
+// AbandonedRunError is the error of a run whose worker stopped before it
+// finished.
+const AbandonedRunError = "the worker stopped before the run finished"
+
+// MemoryRunStore keeps runs in memory, for a single instance and tests.
+type MemoryRunStore struct {
+	mu     sync.Mutex
+	nextID int64
+	runs   []Run
+}
+
+var _ RunStore = (*MemoryRunStore)(nil)
+
+func NewMemoryRunStore() *MemoryRunStore {
+	return &MemoryRunStore{}
+}
+
+func (s *MemoryRunStore) StartRun(_ context.Context, run *Run) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.runs {
+		if other := &s.runs[i]; other.JobID == run.JobID && other.Status == StatusRunning {
+			at := run.StartedAt
+			other.Status, other.Error, other.FinishedAt = StatusFailed, AbandonedRunError, &at
+		}
+	}
+	s.nextID++
+	run.ID = s.nextID
+	s.runs = append(s.runs, *run)
+	return nil
+}
+
+func (s *MemoryRunStore) UpdateRun(_ context.Context, run *Run) error {
+	return s.update(run.ID, func(stored *Run) {
+		stored.Total, stored.Processed, stored.Failed = run.Total, run.Processed, run.Failed
+		stored.ErrorSamples, stored.UpdatedAt = slices.Clone(run.ErrorSamples), run.UpdatedAt
+	})
+}
+
+func (s *MemoryRunStore) FinishRun(_ context.Context, run *Run) error {
+	return s.update(run.ID, func(stored *Run) {
+		stored.Total, stored.Processed, stored.Failed = run.Total, run.Processed, run.Failed
+		stored.ErrorSamples, stored.UpdatedAt = slices.Clone(run.ErrorSamples), run.UpdatedAt
+		stored.Status, stored.Error, stored.FinishedAt = run.Status, run.Error, run.FinishedAt
+	})
+}
+
+func (s *MemoryRunStore) update(id int64, fn func(run *Run)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.runs {
+		if s.runs[i].ID == id {
+			fn(&s.runs[i])
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: job run %d", apperrors.ErrNotFound, id)
+}
+
+func (s *MemoryRunStore) GetRun(_ context.Context, name string, id int64) (*Run, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, run := range s.runs {
+		if run.ID == id && run.Name == name {
+			return &run, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: job %s has no run %d", apperrors.ErrNotFound, name, id)
+}
+
+func (s *MemoryRunStore) ListRuns(_ context.Context, name string, limit int) ([]Run, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	found := []Run{}
+	for i := len(s.runs) - 1; i >= 0 && (limit <= 0 || len(found) < limit); i-- {
+		if s.runs[i].Name == name {
+			found = append(found, s.runs[i])
+		}
+	}
+	return found, nil
+}
@@ -0,0 +1,182 @@
+package jobs
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Filter narrows List. Empty fields match every job.
+type Filter struct {
+	Kind   string
+	Status Status
+	Limit  int
+}
+
+// Store keeps the jobs so that any instance sharing it can run a queued job
+// and answer polls for it. Claim must hand a job to one worker only.
+type Store interface {
+	// Create adds a queued job. It fails with ErrAlreadyExists while another
+	// job has the same DedupeKey.
+	Create(ctx context.Context, job *Job) error
+	// Claim marks the oldest job of one of kinds that is queued, or running
+	// without a heartbeat since staleBefore, as running on worker and returns
+	// it with Attempts counted. It returns nil when there is no such job.
+	Claim(ctx context.Context, kinds []string, worker string, now, staleBefore time.Time) (*Job, error)
+	// Progress records how far a running job got, which also counts as a
+	// heartbeat.
+	Progress(ctx context.Context, id string, processed int, at time.Time) error
+	Heartbeat(ctx context.Context, id string, at time.Time) error
+	// Finish stores the final status, result or error and finished time of
+	// job and drops its payload.
+	Finish(ctx context.Context, job *Job) error
+	// Requeue puts a running job back in the queue to be claimed again.
+	Requeue(ctx context.Context, id string) error
+	Get(ctx context.Context, id string) (*Job, error)
+	// List returns the matching jobs, newest first.
+	List(ctx context.Context, f Filter) ([]Job, error)
+	CountQueued(ctx context.Context) (int, error)
+	DeleteFinishedBefore(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// MemoryStore keeps the jobs of a single instance in memory. They are lost on
+// restart.
+type MemoryStore struct {
+	mu   sync.Mutex
+	jobs map[string]*Job
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{jobs: map[string]*Job{}}
+}
+
+func (s *MemoryStore) Create(_ context.Context, job *Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if job.DedupeKey != "" {
+		for _, other := range s.jobs {
+			if other.DedupeKey == job.DedupeKey {
+				return fmt.Errorf("%w: job %s is already queued", apperrors.ErrAlreadyExists, job.DedupeKey)
+			}
+		}
+	}
+	stored := *job
+	s.jobs[job.ID] = &stored
+	return nil
+}
+
+func (s *MemoryStore) Claim(_ context.Context, kinds []string, worker string, now, staleBefore time.Time) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var next *Job
+	for _, job := range s.jobs {
+		if !slices.Contains(kinds, job.Kind) || !claimable(job, staleBefore) {
+			continue
+		}
+		if next == nil || job.CreatedAt.Before(next.CreatedAt) {
+			next = job
+		}
+	}
+	if next == nil {
+		return nil, nil
+	}
+	next.Status, next.Worker = StatusRunning, worker
+	next.StartedAt, next.HeartbeatAt = &now, &now
+	next.Attempts++
+	claimed := *next
+	return &claimed, nil
+}
+
+func claimable(job *Job, staleBefore time.Time) bool {
+	return job.Status == StatusQueued ||
+		(job.Status == StatusRunning && job.HeartbeatAt != nil && job.HeartbeatAt.Before(staleBefore))
+}
+
+func (s *MemoryStore) Progress(_ context.Context, id string, processed int, at time.Time) error {
+	return s.update(id, func(job *Job) { job.Processed, job.HeartbeatAt = processed, &at })
+}
+
+func (s *MemoryStore) Heartbeat(_ context.Context, id string, at time.Time) error {
+	return s.update(id, func(job *Job) { job.HeartbeatAt = &at })
+}
+
+func (s *MemoryStore) Finish(_ context.Context, finished *Job) error {
+	return s.update(finished.ID, func(job *Job) {
+		job.Status, job.Result, job.Error = finished.Status, finished.Result, finished.Error
+		job.Processed, job.FinishedAt, job.Payload = finished.Processed, finished.FinishedAt, nil
+	})
+}
+
+func (s *MemoryStore) Requeue(_ context.Context, id string) error {
+	return s.update(id, func(job *Job) {
+		job.Status, job.Worker, job.StartedAt, job.HeartbeatAt = StatusQueued, "", nil, nil
+	})
+}
+
+func (s *MemoryStore) update(id string, fn func(job *Job)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return fmt.Errorf("%w: job %s", apperrors.ErrNotFound, id)
+	}
+	fn(job)
+	return nil
+}
+
+func (s *MemoryStore) Get(_ context.Context, id string) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: job %s", apperrors.ErrNotFound, id)
+	}
+	found := *job
+	return &found, nil
+}
+
+func (s *MemoryStore) List(_ context.Context, f Filter) ([]Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	found := []Job{}
+	for _, job := range s.jobs {
+		if (f.Kind == "" || job.Kind == f.Kind) && (f.Status == "" || job.Status == f.Status) {
+			found = append(found, *job)
+		}
+	}
+	slices.SortFunc(found, func(a, b Job) int { return b.CreatedAt.Compare(a.CreatedAt) })
+	if f.Limit > 0 && len(found) > f.Limit {
+		found = found[:f.Limit]
+	}
+	return found, nil
+}
+
+func (s *MemoryStore) CountQueued(_ context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, job := range s.jobs {
+		if job.Status == StatusQueued {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *MemoryStore) DeleteFinishedBefore(_ context.Context, cutoff time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int64
+	for id, job := range s.jobs {
+		if job.FinishedAt != nil && job.FinishedAt.Before(cutoff) {
+			delete(s.jobs, id)
+			n++
+		}
+	}
+	return n, nil
+}
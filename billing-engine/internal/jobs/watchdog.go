@@ -0,0 +1,180 @@
+package jobs
+
+import (
+	"billing-engine/internal/infrastructure/monitoring"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultWatchdogInterval = time.Minute
+	DefaultWatchdogGrace    = 30 * time.Minute
+)
+
+// Schedule gives the next time a batch job is due after t. A cron.Schedule
+// is one.
+type Schedule interface {
+	Next(t time.Time) time.Time
+}
+
+// WatchdogConfig tunes a Watchdog. A job is missed once it is Grace past a
+// scheduled time without a run having started; CancelOverruns cancels a run
+// that goes past its maximum runtime instead of only alerting on it.
+type WatchdogConfig struct {
+	Interval       time.Duration
+	Grace          time.Duration
+	CancelOverruns bool
+}
+
+// Watchdog checks the recorded runs of the scheduled batch jobs for a run
+// that should have started and did not, or one that has been running for too
+// long. Each is logged as an error when it is first seen, counted in
+// billing_engine_batch_job_watchdog_alerts_total and held in a gauge until it
+// clears, so a nightly job failing silently shows up the same night rather
+// than when its data is found stale. Every instance watches the same runs;
+// an overrunning run can only be cancelled by the instance running it.
+type Watchdog struct {
+	recorder *RunRecorder
+	cfg      WatchdogConfig
+	clock    clock.Clock
+	logger   *slog.Logger
+	wg       sync.WaitGroup
+	cancel   context.CancelFunc
+
+	mu      sync.Mutex
+	watched map[string]*watchedJob
+}
+
+type watchedJob struct {
+	schedule   Schedule
+	maxRuntime time.Duration
+	// since is when watching began; slots before it are not missed.
+	since time.Time
+
+	missed bool
+	// overrun is the ID of the run last alerted on as overrunning.
+	overrun int64
+}
+
+// NewWatchdog returns a watchdog over the runs recorder keeps. Zero or less
+// in cfg means DefaultWatchdogInterval and DefaultWatchdogGrace. Times come
+// from clk; nil means the wall clock, which the recorder and cron use too.
+func NewWatchdog(recorder *RunRecorder, cfg WatchdogConfig, clk clock.Clock, logger *slog.Logger) *Watchdog {
+	if recorder == nil || logger == nil {
+		panic("run recorder and logger cannot be nil")
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultWatchdogInterval
+	}
+	if cfg.Grace <= 0 {
+		cfg.Grace = DefaultWatchdogGrace
+	}
+	return &Watchdog{
+		recorder: recorder, cfg: cfg, clock: clock.OrSystem(clk),
+		logger: logger.With("component", "Watchdog"), watched: make(map[string]*watchedJob),
+	}
+}
+
+// Watch has the watchdog check the job called name, due on schedule and
+// allowed to run for maxRuntime. Zero or less for maxRuntime only checks
+// that the job runs.
+func (w *Watchdog) Watch(name string, schedule Schedule, maxRuntime time.Duration) {
+	w.recorder.Register(name)
+	w.mu.Lock()
+	w.watched[name] = &watchedJob{schedule: schedule, maxRuntime: maxRuntime, since: w.clock.Now()}
+	w.mu.Unlock()
+}
+
+// Start checks once right away and then every interval until Stop or until
+// ctx ends.
+func (w *Watchdog) Start(ctx context.Context) {
+	loopCtx, cancel := context.WithCancel(ctx)
+	w.cancel = cancel
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		ticker := time.NewTicker(w.cfg.Interval)
+		defer ticker.Stop()
+		for {
+			w.Check(loopCtx)
+			select {
+			case <-loopCtx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (w *Watchdog) Stop() {
+	if w.cancel == nil {
+		return
+	}
+	w.cancel()
+	w.wg.Wait()
+}
+
+// Check looks at the latest run of every watched job. A job whose runs
+// cannot be read keeps its last state.
+func (w *Watchdog) Check(ctx context.Context) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	names := make([]string, 0, len(w.watched))
+	for name := range w.watched {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		runs, err := w.recorder.List(ctx, name, 1)
+		if err != nil {
+			if ctx.Err() == nil {
+				w.logger.WarnContext(ctx, "Failed to read batch job runs", slog.String("job_name", name), slog.Any("error", err))
+			}
+			continue
+		}
+		var latest *Run
+		if len(runs) > 0 {
+			latest = &runs[0]
+		}
+		w.check(ctx, name, w.watched[name], latest, w.clock.Now())
+	}
+}
+
+func (w *Watchdog) check(ctx context.Context, name string, job *watchedJob, latest *Run, now time.Time) {
+	logger := w.logger.With(slog.String("job_name", name))
+
+	from := job.since
+	if latest != nil && latest.StartedAt.After(from) {
+		from = latest.StartedAt
+	}
+	due := job.schedule.Next(from)
+	// A slot held up by a run still going is left to the overrun check.
+	running := latest != nil && latest.Status == StatusRunning
+	missed := !running && !due.IsZero() && now.After(due.Add(w.cfg.Grace))
+	switch {
+	case missed && !job.missed:
+		monitoring.RecordBatchJobAlert(name, "missed")
+		logger.ErrorContext(ctx, "Batch job missed its scheduled run",
+			slog.Time("due", due), slog.Duration("grace", w.cfg.Grace))
+	case !missed && job.missed:
+		logger.InfoContext(ctx, "Batch job is running on schedule again")
+	}
+	job.missed = missed
+
+	overrunning := running && job.maxRuntime > 0 && now.Sub(latest.StartedAt) > job.maxRuntime
+	if overrunning && job.overrun != latest.ID {
+		job.overrun = latest.ID
+		monitoring.RecordBatchJobAlert(name, "overrun")
+		logger.ErrorContext(ctx, "Batch job run is past its maximum runtime",
+			slog.Int64("run_id", latest.ID), slog.Time("started_at", latest.StartedAt), slog.Duration("max_runtime", job.maxRuntime))
+		if w.cfg.CancelOverruns && w.recorder.Cancel(latest.ID, ErrRunOverran) {
+			logger.WarnContext(ctx, "Cancelled overrunning batch job run", slog.Int64("run_id", latest.ID))
+		}
+	}
+	monitoring.SetBatchJobWatchdog(name, missed, overrunning)
+}
@@ -0,0 +1,140 @@
+package jobs
+
+import (
+	"billing-engine/internal/infrastructure/monitoring"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/robfig/cron/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchdog(t *testing.T) {
+	ctx := context.Background()
+	midnight := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+	nightly, err := cron.ParseStandard("0 2 * * *")
+	require.NoError(t, err)
+	alerts := func(job, reason string) float64 {
+		return testutil.ToFloat64(monitoring.Jobs.AlertsTotal.WithLabelValues(job, reason))
+	}
+
+	t.Run("alerts once on a missed run until the job runs", func(t *testing.T) {
+		clk := clock.NewFake(midnight)
+		recorder := NewRunRecorder(NewMemoryRunStore(), clk, testLogger)
+		watchdog := NewWatchdog(recorder, WatchdogConfig{}, clk, testLogger)
+		watchdog.Watch("MissedJob", nightly, time.Hour)
+		missed := monitoring.Jobs.Missed.WithLabelValues("MissedJob")
+		before := alerts("MissedJob", "missed")
+
+		clk.Advance(2*time.Hour + 20*time.Minute)
+		watchdog.Check(ctx)
+		assert.Equal(t, 0.0, testutil.ToFloat64(missed), "still within the grace period")
+		assert.Equal(t, before, alerts("MissedJob", "missed"))
+
+		clk.Advance(15 * time.Minute)
+		watchdog.Check(ctx)
+		watchdog.Check(ctx)
+		assert.Equal(t, 1.0, testutil.ToFloat64(missed))
+		assert.Equal(t, before+1, alerts("MissedJob", "missed"), "alerted once")
+
+		require.NoError(t, recorder.Track(ctx, "MissedJob", "job-1", func(context.Context) error { return nil }))
+		watchdog.Check(ctx)
+		assert.Equal(t, 0.0, testutil.ToFloat64(missed))
+	})
+
+	t.Run("slots before watching began are not missed", func(t *testing.T) {
+		clk := clock.NewFake(midnight.Add(5 * time.Hour))
+		watchdog := NewWatchdog(NewRunRecorder(NewMemoryRunStore(), clk, testLogger), WatchdogConfig{}, clk, testLogger)
+		watchdog.Watch("LateStart", nightly, 0)
+
+		watchdog.Check(ctx)
+
+		assert.Equal(t, 0.0, testutil.ToFloat64(monitoring.Jobs.Missed.WithLabelValues("LateStart")))
+	})
+
+	overrun := func(name string, cancelOverruns bool) (*RunRecorder, *clock.Fake, *Watchdog, chan struct{}, chan error) {
+		clk := clock.NewFake(midnight.Add(2 * time.Hour))
+		recorder := NewRunRecorder(NewMemoryRunStore(), clk, testLogger)
+		watchdog := NewWatchdog(recorder, WatchdogConfig{CancelOverruns: cancelOverruns}, clk, testLogger)
+		watchdog.Watch(name, nightly, 30*time.Minute)
+
+		started, release := make(chan struct{}), make(chan struct{})
+		done := make(chan error, 1)
+		go func() {
+			done <- recorder.Track(ctx, name, "job-1", func(ctx context.Context) error {
+				close(started)
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-release:
+					return nil
+				}
+			})
+		}()
+		<-started
+		return recorder, clk, watchdog, release, done
+	}
+
+	t.Run("cancels an overrunning run", func(t *testing.T) {
+		before := alerts("StuckJob", "overrun")
+		recorder, clk, watchdog, _, done := overrun("StuckJob", true)
+
+		clk.Advance(29 * time.Minute)
+		watchdog.Check(ctx)
+		assert.Equal(t, before, alerts("StuckJob", "overrun"))
+
+		clk.Advance(2 * time.Minute)
+		watchdog.Check(ctx)
+		assert.Equal(t, 1.0, testutil.ToFloat64(monitoring.Jobs.Overrunning.WithLabelValues("StuckJob")))
+		assert.Equal(t, before+1, alerts("StuckJob", "overrun"))
+
+		err := <-done
+		assert.ErrorIs(t, err, ErrRunOverran)
+		assert.ErrorIs(t, err, context.Canceled)
+		runs, err := recorder.List(ctx, "StuckJob", 1)
+		require.NoError(t, err)
+		assert.Equal(t, StatusFailed, runs[0].Status)
+		assert.Contains(t, runs[0].Error, ErrRunOverran.Error())
+
+		watchdog.Check(ctx)
+		assert.Equal(t, 0.0, testutil.ToFloat64(monitoring.Jobs.Overrunning.WithLabelValues("StuckJob")))
+	})
+
+	t.Run("only alerts on an overrunning run unless told to cancel", func(t *testing.T) {
+		before := alerts("SlowJob", "overrun")
+		_, clk, watchdog, release, done := overrun("SlowJob", false)
+
+		clk.Advance(time.Hour)
+		watchdog.Check(ctx)
+		watchdog.Check(ctx)
+		assert.Equal(t, before+1, alerts("SlowJob", "overrun"), "alerted once per run")
+		clk.Advance(24 * time.Hour)
+		watchdog.Check(ctx)
+		assert.Equal(t, 0.0, testutil.ToFloat64(monitoring.Jobs.Missed.WithLabelValues("SlowJob")), "the next slot waits for the run")
+
+		close(release)
+		assert.NoError(t, <-done)
+	})
+}
+
+func TestRunRecorderCancel(t *testing.T) {
+	recorder := NewRunRecorder(NewMemoryRunStore(), nil, testLogger)
+	cause := errors.New("stop")
+
+	assert.False(t, recorder.Cancel(1, cause), "no run in progress")
+
+	err := recorder.Track(context.Background(), "Nightly", "job-1", func(ctx context.Context) error {
+		runs, err := recorder.List(ctx, "Nightly", 1)
+		require.NoError(t, err)
+		assert.True(t, recorder.Cancel(runs[0].ID, cause))
+		assert.ErrorIs(t, context.Cause(ctx), cause)
+		return nil
+	})
+
+	assert.NoError(t, err, "a run that finishes anyway keeps its result")
+}
@@ -3,6 +3,8 @@ package apperrors
 import (
 	"errors"
 	"fmt"
+	"slices"
+	"strconv"
 )
 
 var (
@@ -22,11 +24,15 @@ var (
 
 	ErrLoanFullyPaid = errors.New("loan is already fully paid")
 
+	ErrLoanOnHold = errors.New("loan is on hold")
+
 	ErrUnauthorized = errors.New("unauthorized")
 
 	ErrForbidden = errors.New("forbidden")
 
 	ErrConflict = errors.New("resource conflict")
+
+	ErrUnavailable = errors.New("service unavailable")
 )
 
 type ValidationError struct {
@@ -51,10 +57,16 @@ func NewValidationError(field, message string) error {
 	return fmt.Errorf("%w: %w", ErrValidation, &ValidationError{Field: field, Message: message})
 }
 
+// AppError is an error with a Code, and optionally metadata for clients,
+// made with New or Wrap. It matches the sentinels of its code under
+// errors.Is, so callers need not know whether they got one or a sentinel.
 type AppError struct {
-	Code    string
+	Code    Code
 	Message string
 	Cause   error
+	// Meta holds details a client can act on, such as the ID of a
+	// conflicting record. It is sent along with public errors.
+	Meta map[string]string
 }
 
 func (e *AppError) Error() string {
@@ -68,10 +80,86 @@ func (e *AppError) Unwrap() error {
 	return e.Cause
 }
 
+func (e *AppError) Is(target error) bool {
+	m, ok := codeMapping(e.Code)
+	return ok && slices.Contains(m.Errs, target)
+}
+
+// WithMeta sets a metadata entry and returns e.
+func (e *AppError) WithMeta(key, value string) *AppError {
+	if e.Meta == nil {
+		e.Meta = make(map[string]string)
+	}
+	e.Meta[key] = value
+	return e
+}
+
+func New(code Code, message string) *AppError {
+	return &AppError{Code: code, Message: message}
+}
+
+// Wrap gives cause a code and a message of its own, which replaces cause's
+// in Error, so cause may carry internals.
+func Wrap(cause error, code Code, message string) *AppError {
+	return &AppError{Code: code, Message: message, Cause: cause}
+}
+
+// Metadata merges the metadata of every AppError in err's chain, the
+// outermost winning. It is nil when there is none.
+func Metadata(err error) map[string]string {
+	meta := make(map[string]string)
+	collectMeta(err, meta)
+	if len(meta) == 0 {
+		return nil
+	}
+	return meta
+}
+
+func collectMeta(err error, meta map[string]string) {
+	switch e := err.(type) {
+	case *AppError:
+		for key, value := range e.Meta {
+			if _, set := meta[key]; !set {
+				meta[key] = value
+			}
+		}
+		collectMeta(e.Cause, meta)
+	case interface{ Unwrap() []error }:
+		for _, inner := range e.Unwrap() {
+			collectMeta(inner, meta)
+		}
+	case interface{ Unwrap() error }:
+		collectMeta(e.Unwrap(), meta)
+	}
+}
+
 func WrapDatabaseError(cause error, message string) error {
 	return &AppError{
-		Code:    "DB_ERROR",
+		Code:    CodeDatabase,
 		Message: message,
 		Cause:   fmt.Errorf("%w: %w", ErrDatabase, cause),
 	}
 }
+
+// PaymentAmountError reports a payment that does not settle the installment,
+// with the amount that would, so clients can retry with it.
+type PaymentAmountError struct {
+	Amount   float64
+	Expected float64
+	// Digits is the number of decimals of the currency, used for formatting.
+	Digits int
+}
+
+func (e *PaymentAmountError) Error() string {
+	return fmt.Sprintf("%v: payment amount %.*f does not match due amount %s",
+		ErrInvalidPaymentAmount, e.Digits, e.Amount, e.ExpectedString())
+}
+
+// ExpectedString formats the expected amount with the currency's decimals.
+func (e *PaymentAmountError) ExpectedString() string {
+	return strconv.FormatFloat(e.Expected, 'f', e.Digits, 64)
+}
+
+func (e *PaymentAmountError) Unwrap() error {
+	return ErrInvalidPaymentAmount
+}
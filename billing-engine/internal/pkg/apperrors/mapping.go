@@ -0,0 +1,107 @@
+package apperrors
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+)
+
+// Code names a class of error for clients, whatever the transport. It is
+// sent as error.code in REST responses.
+type Code string
+
+const (
+	CodeNotFound           Code = "NOT_FOUND"
+	CodeInvalidArgument    Code = "INVALID_ARGUMENT"
+	CodeFailedPrecondition Code = "FAILED_PRECONDITION"
+	CodeAlreadyExists      Code = "ALREADY_EXISTS"
+	CodeConflict           Code = "CONFLICT"
+	CodeUnauthenticated    Code = "UNAUTHENTICATED"
+	CodePermissionDenied   Code = "PERMISSION_DENIED"
+	CodeUnavailable        Code = "UNAVAILABLE"
+	CodeDatabase           Code = "DB_ERROR"
+	CodeInternal           Code = "INTERNAL"
+)
+
+// GRPCCode is a gRPC status code, numbered as in google.golang.org/grpc/codes
+// so that a gRPC server can convert it with codes.Code(c).
+type GRPCCode uint32
+
+const (
+	GRPCInvalidArgument    GRPCCode = 3
+	GRPCNotFound           GRPCCode = 5
+	GRPCAlreadyExists      GRPCCode = 6
+	GRPCPermissionDenied   GRPCCode = 7
+	GRPCFailedPrecondition GRPCCode = 9
+	GRPCAborted            GRPCCode = 10
+	GRPCInternal           GRPCCode = 13
+	GRPCUnavailable        GRPCCode = 14
+	GRPCUnauthenticated    GRPCCode = 16
+)
+
+// Mapping is how the errors of one Code reach clients.
+type Mapping struct {
+	Code       Code
+	HTTPStatus int
+	GRPCCode   GRPCCode
+	// Public means the error's own message may be shown to clients. Other
+	// errors get a generic message for their status, since theirs can carry
+	// internals such as SQL.
+	Public bool
+	// Retryable hints that the same request may succeed later unchanged.
+	Retryable bool
+	// Errs are the sentinels that have this code. An AppError with the code
+	// matches them under errors.Is.
+	Errs []error
+}
+
+// mappings is checked in order, so an error wrapping sentinels of two codes
+// takes the first.
+var mappings = []Mapping{
+	{Code: CodeNotFound, HTTPStatus: http.StatusNotFound, GRPCCode: GRPCNotFound, Errs: []error{ErrNotFound}},
+	{Code: CodeInvalidArgument, HTTPStatus: http.StatusBadRequest, GRPCCode: GRPCInvalidArgument, Public: true,
+		Errs: []error{ErrInvalidArgument, ErrValidation, ErrInvalidPaymentAmount}},
+	{Code: CodeFailedPrecondition, HTTPStatus: http.StatusBadRequest, GRPCCode: GRPCFailedPrecondition, Public: true,
+		Errs: []error{ErrLoanFullyPaid}},
+	{Code: CodeAlreadyExists, HTTPStatus: http.StatusConflict, GRPCCode: GRPCAlreadyExists, Public: true, Errs: []error{ErrAlreadyExists}},
+	{Code: CodeConflict, HTTPStatus: http.StatusConflict, GRPCCode: GRPCAborted, Public: true, Errs: []error{ErrConflict, ErrLoanOnHold}},
+	{Code: CodeUnauthenticated, HTTPStatus: http.StatusUnauthorized, GRPCCode: GRPCUnauthenticated, Errs: []error{ErrUnauthorized}},
+	{Code: CodePermissionDenied, HTTPStatus: http.StatusForbidden, GRPCCode: GRPCPermissionDenied, Errs: []error{ErrForbidden}},
+	{Code: CodeUnavailable, HTTPStatus: http.StatusServiceUnavailable, GRPCCode: GRPCUnavailable, Public: true, Retryable: true,
+		Errs: []error{ErrUnavailable}},
+	{Code: CodeDatabase, HTTPStatus: http.StatusInternalServerError, GRPCCode: GRPCInternal, Errs: []error{ErrDatabase}},
+	{Code: CodeInternal, HTTPStatus: http.StatusInternalServerError, GRPCCode: GRPCInternal, Errs: []error{ErrInternalServer}},
+}
+
+// internal is the mapping of errors that match none.
+var internal = mappings[len(mappings)-1]
+
+// Lookup returns the mapping of err: the first whose sentinels err matches,
+// or CodeInternal's.
+func Lookup(err error) Mapping {
+	for _, m := range mappings {
+		if slices.ContainsFunc(m.Errs, func(target error) bool { return errors.Is(err, target) }) {
+			return m
+		}
+	}
+	return internal
+}
+
+// HTTPStatus is the status a REST response to err has.
+func HTTPStatus(err error) int {
+	return Lookup(err).HTTPStatus
+}
+
+// IsRetryable reports whether the request that failed with err may succeed
+// when sent again as it was, such as when a dependency is down.
+func IsRetryable(err error) bool {
+	return Lookup(err).Retryable
+}
+
+func codeMapping(code Code) (Mapping, bool) {
+	i := slices.IndexFunc(mappings, func(m Mapping) bool { return m.Code == code })
+	if i < 0 {
+		return Mapping{}, false
+	}
+	return mappings[i], true
+}
@@ -0,0 +1,67 @@
+package apperrors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		code   Code
+		status int
+	}{
+		{"sentinel", fmt.Errorf("%w: loan 7", ErrNotFound), CodeNotFound, http.StatusNotFound},
+		{"validation", NewValidationError("amount", "must be positive"), CodeInvalidArgument, http.StatusBadRequest},
+		{"payment amount", &PaymentAmountError{Amount: 1, Expected: 2}, CodeInvalidArgument, http.StatusBadRequest},
+		{"app error", fmt.Errorf("assign: %w", New(CodeConflict, "loan taken")), CodeConflict, http.StatusConflict},
+		{"first sentinel wins", fmt.Errorf("%w: %w", ErrConflict, ErrDatabase), CodeConflict, http.StatusConflict},
+		{"database", WrapDatabaseError(errors.New("deadlock"), "failed to save"), CodeDatabase, http.StatusInternalServerError},
+		{"unknown", errors.New("boom"), CodeInternal, http.StatusInternalServerError},
+		{"unknown code", New("TEST_CODE", "boom"), CodeInternal, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := Lookup(tt.err)
+			if m.Code != tt.code || m.HTTPStatus != tt.status {
+				t.Errorf("expected %s/%d, got %s/%d", tt.code, tt.status, m.Code, m.HTTPStatus)
+			}
+		})
+	}
+
+	if !IsRetryable(fmt.Errorf("%w: storage is not configured", ErrUnavailable)) || IsRetryable(ErrConflict) {
+		t.Error("only unavailable errors are retryable")
+	}
+	if Lookup(ErrForbidden).GRPCCode != GRPCPermissionDenied {
+		t.Error("forbidden should map to PermissionDenied")
+	}
+}
+
+func TestAppErrorIs(t *testing.T) {
+	err := Wrap(errors.New("unique violation"), CodeAlreadyExists, "customer exists")
+	if !errors.Is(err, ErrAlreadyExists) {
+		t.Error("expected the error to match its code's sentinel")
+	}
+	if errors.Is(err, ErrConflict) {
+		t.Error("expected the error not to match another code's sentinel")
+	}
+	if got := err.Error(); got != "[ALREADY_EXISTS] customer exists" {
+		t.Errorf("expected the cause to stay out of the message, got %q", got)
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	inner := New(CodeConflict, "loan taken").WithMeta("loanId", "7").WithMeta("customerId", "1")
+	outer := Wrap(inner, CodeConflict, "cannot assign").WithMeta("customerId", "2")
+	meta := Metadata(fmt.Errorf("handler: %w", errors.Join(errors.New("other"), outer)))
+
+	if meta["loanId"] != "7" || meta["customerId"] != "2" || len(meta) != 2 {
+		t.Errorf("unexpected metadata %v", meta)
+	}
+	if Metadata(ErrNotFound) != nil {
+		t.Error("expected no metadata for a sentinel")
+	}
+}
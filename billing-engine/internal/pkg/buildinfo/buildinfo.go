@@ -0,0 +1,70 @@
+// Package buildinfo says which build of the service is running. Version,
+// Commit and Date are set at link time, as make build does:
+//
+//	go build -ldflags "-X billing-engine/internal/pkg/buildinfo.Version=v1.4.0 \
+//	    -X billing-engine/internal/pkg/buildinfo.Commit=$(git rev-parse HEAD) \
+//	    -X billing-engine/internal/pkg/buildinfo.Date=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd
+//
+// A plain go build inside the repository still gets the commit and its time
+// from the VCS information the toolchain records.
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+const devVersion = "dev"
+
+var (
+	Version = devVersion
+	Commit  string
+	Date    string
+)
+
+// Info is the build of the running binary. Modified is true when it was
+// built from a working tree with uncommitted changes, as far as is known.
+type Info struct {
+	Version   string `json:"version" example:"v1.4.0"`
+	Commit    string `json:"commit" example:"7021e71c0ae5a3b7d9d1e5f54f0c2d1d3c9b8a41"`
+	Date      string `json:"buildDate,omitempty" example:"2025-03-03T09:00:00Z"`
+	Modified  bool   `json:"modified,omitempty"`
+	GoVersion string `json:"goVersion" example:"go1.24.2"`
+}
+
+// Get returns the link-time values, completed from the recorded VCS
+// information where they were not set.
+func Get() Info {
+	info := Info{Version: Version, Commit: Commit, Date: Date, GoVersion: runtime.Version()}
+	build, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	for _, setting := range build.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if info.Commit == "" {
+				info.Commit = setting.Value
+			}
+		case "vcs.time":
+			if info.Date == "" {
+				info.Date = setting.Value
+			}
+		case "vcs.modified":
+			info.Modified = setting.Value == "true" && Commit == ""
+		}
+	}
+	if info.Version == devVersion && build.Main.Version != "" && build.Main.Version != "(devel)" {
+		info.Version = build.Main.Version
+	}
+	return info
+}
+
+// Release names the build for an error tracker: its version unless that is
+// the dev default, else its commit, else empty.
+func (i Info) Release() string {
+	if i.Version != devVersion {
+		return i.Version
+	}
+	return i.Commit
+}
@@ -0,0 +1,22 @@
+package buildinfo
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGet(t *testing.T) {
+	defer func(version, commit, date string) { Version, Commit, Date = version, commit, date }(Version, Commit, Date)
+
+	Version, Commit, Date = "v1.4.0", "7021e71", "2025-03-03T09:00:00Z"
+	info := Get()
+	assert.Equal(t, Info{Version: "v1.4.0", Commit: "7021e71", Date: "2025-03-03T09:00:00Z", GoVersion: runtime.Version()}, info)
+	assert.Equal(t, "v1.4.0", info.Release())
+}
+
+func TestRelease(t *testing.T) {
+	assert.Equal(t, "7021e71", Info{Version: devVersion, Commit: "7021e71"}.Release())
+	assert.Empty(t, Info{Version: devVersion}.Release())
+}
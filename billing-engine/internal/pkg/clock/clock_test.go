@@ -0,0 +1,50 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSystem(t *testing.T) {
+	before := time.Now()
+	now := System().Now()
+	assert.False(t, now.Before(before))
+}
+
+func TestOrSystem(t *testing.T) {
+	fake := NewFake(time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC))
+	assert.Same(t, fake, OrSystem(fake))
+	assert.Equal(t, System(), OrSystem(nil))
+}
+
+func TestFake(t *testing.T) {
+	start := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	fake := NewFake(start)
+	assert.Equal(t, start, fake.Now())
+	assert.Equal(t, start, fake.Now(), "a fake clock does not move on its own")
+
+	fake.Advance(48 * time.Hour)
+	assert.Equal(t, start.AddDate(0, 0, 2), fake.Now())
+
+	fake.Set(start)
+	assert.Equal(t, start, fake.Now())
+}
+
+func TestOffset(t *testing.T) {
+	start := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	base := NewFake(start)
+	offset := NewOffset(base)
+	assert.Equal(t, start, offset.Now())
+
+	offset.Advance(7 * 24 * time.Hour)
+	assert.Equal(t, start.AddDate(0, 0, 7), offset.Now())
+	assert.Equal(t, 7*24*time.Hour, offset.Offset())
+
+	base.Advance(time.Hour)
+	assert.Equal(t, start.AddDate(0, 0, 7).Add(time.Hour), offset.Now(), "the offset clock keeps following its base")
+
+	offset.Advance(-time.Hour)
+	assert.Equal(t, 7*24*time.Hour, offset.Offset(), "the offset clock does not go back")
+}
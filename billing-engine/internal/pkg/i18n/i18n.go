@@ -0,0 +1,125 @@
+// Package i18n chooses the language of API messages and holds their
+// translations. English is the default and every message exists in it.
+package i18n
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"fmt"
+
+	"golang.org/x/text/language"
+)
+
+const (
+	English    = "en"
+	Indonesian = "id"
+
+	Default = English
+)
+
+// Supported lists the locales with a catalog, the default first.
+var Supported = []string{English, Indonesian}
+
+var matcher = language.NewMatcher([]language.Tag{language.English, language.Indonesian})
+
+type Key string
+
+const (
+	MsgInternal      Key = "internal"
+	MsgNotFound      Key = "not_found"
+	MsgUnauthorized  Key = "unauthorized"
+	MsgForbidden     Key = "forbidden"
+	MsgBodyTooLarge  Key = "body_too_large"
+	MsgRateLimited   Key = "rate_limited"
+	MsgClientBlocked Key = "client_blocked"
+)
+
+var catalogs = map[string]map[Key]string{
+	English: {
+		MsgInternal:      "An unexpected error occurred.",
+		MsgNotFound:      "Resource not found.",
+		MsgUnauthorized:  "Unauthorized",
+		MsgForbidden:     "Forbidden",
+		MsgBodyTooLarge:  "Request body exceeds the %d byte limit.",
+		MsgRateLimited:   "Rate limit exceeded",
+		MsgClientBlocked: "Client is blocked",
+	},
+	Indonesian: {
+		MsgInternal:      "Terjadi kesalahan yang tidak terduga.",
+		MsgNotFound:      "Data tidak ditemukan.",
+		MsgUnauthorized:  "Tidak terautentikasi",
+		MsgForbidden:     "Akses ditolak",
+		MsgBodyTooLarge:  "Isi permintaan melebihi batas %d byte.",
+		MsgRateLimited:   "Batas permintaan terlampaui",
+		MsgClientBlocked: "Klien diblokir",
+	},
+}
+
+// Negotiate returns the supported locale that best matches an
+// Accept-Language header, so "id-ID,id;q=0.9" gives Indonesian. A missing or
+// malformed header, or one naming only unsupported languages, gives Default.
+func Negotiate(acceptLanguage string) string {
+	if acceptLanguage == "" {
+		return Default
+	}
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return Default
+	}
+	_, index, confidence := matcher.Match(tags...)
+	if confidence == language.No {
+		return Default
+	}
+	return Supported[index]
+}
+
+// Message formats key in locale. Locales without a catalog use English.
+func Message(locale string, key Key, args ...any) string {
+	catalog, ok := catalogs[locale]
+	if !ok {
+		catalog = catalogs[Default]
+	}
+	text, ok := catalog[key]
+	if !ok {
+		text = catalogs[Default][key]
+	}
+	if len(args) == 0 {
+		return text
+	}
+	return fmt.Sprintf(text, args...)
+}
+
+// errorKeys holds the generic messages of the codes whose errors are not
+// public; the rest fall back to MsgInternal.
+var errorKeys = map[apperrors.Code]Key{
+	apperrors.CodeNotFound:         MsgNotFound,
+	apperrors.CodeUnauthenticated:  MsgUnauthorized,
+	apperrors.CodePermissionDenied: MsgForbidden,
+}
+
+// ErrorMessage is what a client is told about err in locale: err's own
+// message when its code is public, a translated generic one otherwise.
+func ErrorMessage(locale string, err error) string {
+	m := apperrors.Lookup(err)
+	if m.Public {
+		return err.Error()
+	}
+	if key, ok := errorKeys[m.Code]; ok {
+		return Message(locale, key)
+	}
+	return Message(locale, MsgInternal)
+}
+
+type contextKey struct{}
+
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, contextKey{}, locale)
+}
+
+// FromContext returns the locale negotiated for the request, or Default.
+func FromContext(ctx context.Context) string {
+	if locale, ok := ctx.Value(contextKey{}).(string); ok {
+		return locale
+	}
+	return Default
+}
@@ -0,0 +1,52 @@
+package i18n
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiate(t *testing.T) {
+	cases := map[string]string{
+		"":                          English,
+		"id":                        Indonesian,
+		"id-ID,id;q=0.9,en;q=0.8":   Indonesian,
+		"en-US,id;q=0.5":            English,
+		"fr-FR, de;q=0.8":           English,
+		"fr;q=0.9, id;q=0.5":        Indonesian,
+		"not a language tag;;q=x,,": English,
+	}
+	for header, want := range cases {
+		assert.Equal(t, want, Negotiate(header), "Accept-Language %q", header)
+	}
+}
+
+func TestMessage(t *testing.T) {
+	assert.Equal(t, "Resource not found.", Message(English, MsgNotFound))
+	assert.Equal(t, "Data tidak ditemukan.", Message(Indonesian, MsgNotFound))
+	assert.Equal(t, "Isi permintaan melebihi batas 1024 byte.", Message(Indonesian, MsgBodyTooLarge, 1024))
+	assert.Equal(t, "Forbidden", Message("fr", MsgForbidden))
+}
+
+func TestCatalogsAreComplete(t *testing.T) {
+	for _, locale := range Supported {
+		for key := range catalogs[Default] {
+			assert.NotEmpty(t, catalogs[locale][key], "%s has no %q", locale, key)
+		}
+	}
+}
+
+func TestLocaleContext(t *testing.T) {
+	assert.Equal(t, Default, FromContext(context.Background()))
+	assert.Equal(t, Indonesian, FromContext(WithLocale(context.Background(), Indonesian)))
+}
+
+func TestErrorMessage(t *testing.T) {
+	assert.Equal(t, "Data tidak ditemukan.", ErrorMessage(Indonesian, fmt.Errorf("%w: loan 7", apperrors.ErrNotFound)))
+	assert.Equal(t, "invalid argument: bad date", ErrorMessage(Indonesian, fmt.Errorf("%w: bad date", apperrors.ErrInvalidArgument)))
+	assert.Equal(t, "An unexpected error occurred.", ErrorMessage(English, errors.New(`relation "loans" does not exist`)))
+}
@@ -0,0 +1,139 @@
+// Package retry calls an operation again after a failure, waiting longer
+// each time, until it succeeds, fails for good or runs out of attempts.
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+const (
+	DefaultMaxAttempts = 3
+	DefaultInitial     = 100 * time.Millisecond
+	DefaultMax         = 30 * time.Second
+	DefaultMultiplier  = 2
+)
+
+// Policy says how often and how far apart an operation is tried. Zero
+// fields take the defaults, except Jitter, which is off at zero.
+type Policy struct {
+	// MaxAttempts counts the first call.
+	MaxAttempts int
+	// Initial is the wait before the second attempt. Each later wait is
+	// Multiplier times the one before, up to Max.
+	Initial    time.Duration
+	Max        time.Duration
+	Multiplier float64
+	// Jitter takes up to that fraction, between 0 and 1, off every wait at
+	// random, so that instances failing together do not retry together.
+	Jitter float64
+	// Retryable reports whether an error is worth another attempt; nil
+	// retries every error not marked Permanent.
+	Retryable func(error) bool
+	// OnRetry is called before each wait with the attempt that failed, for
+	// logging.
+	OnRetry func(attempt int, err error, wait time.Duration)
+}
+
+func (p Policy) withDefaults() Policy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = DefaultMaxAttempts
+	}
+	if p.Initial <= 0 {
+		p.Initial = DefaultInitial
+	}
+	if p.Max <= 0 {
+		p.Max = DefaultMax
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = DefaultMultiplier
+	}
+	p.Jitter = min(max(p.Jitter, 0), 1)
+	return p
+}
+
+// Backoff is the wait after the failed attempt, counted from 1, before
+// jitter.
+func (p Policy) Backoff(attempt int) time.Duration {
+	p = p.withDefaults()
+	wait := float64(p.Initial)
+	for i := 1; i < attempt && wait < float64(p.Max); i++ {
+		wait *= p.Multiplier
+	}
+	return min(time.Duration(wait), p.Max)
+}
+
+func (p Policy) wait(attempt int) time.Duration {
+	wait := p.Backoff(attempt)
+	if p.Jitter > 0 {
+		wait -= time.Duration(p.Jitter * rand.Float64() * float64(wait))
+	}
+	return wait
+}
+
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth another attempt. Do returns err itself,
+// or the error wrapping it.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err: err}
+}
+
+// IsPermanent reports whether err, or an error it wraps, was marked
+// Permanent.
+func IsPermanent(err error) bool {
+	var permanent permanentError
+	return errors.As(err, &permanent)
+}
+
+// Do calls fn, with the attempt counted from 1, until it returns nil. It
+// stops early with the error of an attempt that was marked Permanent or is
+// not Retryable, and returns the last error once MaxAttempts have failed.
+// When ctx ends during a wait it returns the context's error, wrapping the
+// last error too.
+func Do(ctx context.Context, p Policy, fn func(ctx context.Context, attempt int) error) error {
+	p = p.withDefaults()
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx, attempt)
+		if err == nil {
+			return nil
+		}
+		if permanent, ok := err.(permanentError); ok {
+			return permanent.err
+		}
+		if IsPermanent(err) {
+			return err
+		}
+		if attempt >= p.MaxAttempts || (p.Retryable != nil && !p.Retryable(err)) {
+			return err
+		}
+
+		wait := p.wait(attempt)
+		if p.OnRetry != nil {
+			p.OnRetry(attempt, err, wait)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w, last attempt: %w", ctx.Err(), err)
+		case <-timer.C:
+		}
+	}
+}
+
+// RetryableStatus reports whether an HTTP response with code may succeed
+// when sent again: a timeout, rate limiting or a server error.
+func RetryableStatus(code int) bool {
+	return code == http.StatusRequestTimeout || code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+}
@@ -0,0 +1,115 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errFlaky = errors.New("connection refused")
+
+func TestDo(t *testing.T) {
+	ctx := context.Background()
+	fast := Policy{MaxAttempts: 4, Initial: time.Millisecond, Max: 2 * time.Millisecond}
+
+	t.Run("retries until it succeeds", func(t *testing.T) {
+		var waits []time.Duration
+		p := fast
+		p.OnRetry = func(_ int, _ error, wait time.Duration) { waits = append(waits, wait) }
+		calls := 0
+
+		err := Do(ctx, p, func(_ context.Context, attempt int) error {
+			calls++
+			assert.Equal(t, calls, attempt)
+			if attempt < 3 {
+				return errFlaky
+			}
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 3, calls)
+		assert.Equal(t, []time.Duration{time.Millisecond, 2 * time.Millisecond}, waits)
+	})
+
+	t.Run("returns the last error once the attempts run out", func(t *testing.T) {
+		calls := 0
+		err := Do(ctx, fast, func(context.Context, int) error {
+			calls++
+			return fmt.Errorf("attempt %d: %w", calls, errFlaky)
+		})
+
+		assert.ErrorIs(t, err, errFlaky)
+		assert.EqualError(t, err, "attempt 4: connection refused")
+		assert.Equal(t, 4, calls)
+	})
+
+	t.Run("stops on a permanent or unretryable error", func(t *testing.T) {
+		calls := 0
+		err := Do(ctx, fast, func(context.Context, int) error {
+			calls++
+			return Permanent(errFlaky)
+		})
+		assert.Same(t, errFlaky, err)
+		assert.Equal(t, 1, calls)
+
+		err = Do(ctx, fast, func(context.Context, int) error {
+			return fmt.Errorf("dial: %w", Permanent(errFlaky))
+		})
+		assert.EqualError(t, err, "dial: connection refused", "the wrapping error is kept")
+		assert.True(t, IsPermanent(err))
+
+		calls = 0
+		p := fast
+		p.Retryable = func(err error) bool { return !errors.Is(err, errFlaky) }
+		err = Do(ctx, p, func(context.Context, int) error {
+			calls++
+			return errFlaky
+		})
+		assert.ErrorIs(t, err, errFlaky)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("gives up when the context ends", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		p := Policy{MaxAttempts: 5, Initial: time.Hour}
+		p.OnRetry = func(int, error, time.Duration) { cancel() }
+
+		err := Do(ctx, p, func(context.Context, int) error { return errFlaky })
+
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.ErrorIs(t, err, errFlaky)
+	})
+}
+
+func TestPolicyBackoff(t *testing.T) {
+	p := Policy{Initial: time.Second, Max: 5 * time.Second}
+	assert.Equal(t, time.Second, p.Backoff(1))
+	assert.Equal(t, 2*time.Second, p.Backoff(2))
+	assert.Equal(t, 4*time.Second, p.Backoff(3))
+	assert.Equal(t, 5*time.Second, p.Backoff(4), "capped at Max")
+	assert.Equal(t, 5*time.Second, p.Backoff(100))
+
+	assert.Equal(t, DefaultInitial, Policy{}.Backoff(1))
+
+	p.Jitter = 0.5
+	for attempt := 1; attempt <= 4; attempt++ {
+		wait := p.wait(attempt)
+		assert.LessOrEqual(t, wait, p.Backoff(attempt))
+		assert.GreaterOrEqual(t, wait, p.Backoff(attempt)/2)
+	}
+}
+
+func TestRetryableStatus(t *testing.T) {
+	for _, code := range []int{http.StatusRequestTimeout, http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusServiceUnavailable} {
+		assert.True(t, RetryableStatus(code), code)
+	}
+	for _, code := range []int{http.StatusOK, http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound} {
+		assert.False(t, RetryableStatus(code), code)
+	}
+}
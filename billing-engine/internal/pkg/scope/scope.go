@@ -0,0 +1,43 @@
+package scope
+
+import "context"
+
+type contextKey string
+
+const (
+	customerKey contextKey = "scope.customer"
+	tenantKey   contextKey = "scope.tenant"
+)
+
+// DefaultTenant is the tenant of tokens without a tenant claim and of rows
+// written outside any tenant's scope, such as by batch jobs.
+const DefaultTenant = "default"
+
+func WithCustomer(ctx context.Context, customerID int64) context.Context {
+	return context.WithValue(ctx, customerKey, customerID)
+}
+
+func CustomerFromContext(ctx context.Context) (int64, bool) {
+	customerID, ok := ctx.Value(customerKey).(int64)
+	return customerID, ok
+}
+
+// WithTenant limits what ctx reaches to the tenant's loans and customers.
+// The repositories add the condition to their queries; a context without a
+// tenant, as batch jobs run with, reaches every tenant.
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantKey, tenantID)
+}
+
+func TenantFromContext(ctx context.Context) (string, bool) {
+	tenantID, ok := ctx.Value(tenantKey).(string)
+	return tenantID, ok
+}
+
+// TenantOrDefault is the tenant new rows written under ctx belong to.
+func TenantOrDefault(ctx context.Context) string {
+	if tenantID, ok := TenantFromContext(ctx); ok {
+		return tenantID
+	}
+	return DefaultTenant
+}
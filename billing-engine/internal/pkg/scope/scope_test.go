@@ -0,0 +1,38 @@
+package scope
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCustomerScope(t *testing.T) {
+	t.Run("returns false when no customer scope is set", func(t *testing.T) {
+		_, ok := CustomerFromContext(context.Background())
+		assert.False(t, ok)
+	})
+
+	t.Run("returns the customer ID stored in the context", func(t *testing.T) {
+		ctx := WithCustomer(context.Background(), 42)
+		customerID, ok := CustomerFromContext(ctx)
+		assert.True(t, ok)
+		assert.Equal(t, int64(42), customerID)
+	})
+}
+
+func TestTenantScope(t *testing.T) {
+	t.Run("reaches every tenant without a tenant scope", func(t *testing.T) {
+		_, ok := TenantFromContext(context.Background())
+		assert.False(t, ok)
+		assert.Equal(t, DefaultTenant, TenantOrDefault(context.Background()))
+	})
+
+	t.Run("returns the tenant stored in the context", func(t *testing.T) {
+		ctx := WithTenant(context.Background(), "acme")
+		tenantID, ok := TenantFromContext(ctx)
+		assert.True(t, ok)
+		assert.Equal(t, "acme", tenantID)
+		assert.Equal(t, "acme", TenantOrDefault(ctx))
+	})
+}
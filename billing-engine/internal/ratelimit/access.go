@@ -0,0 +1,220 @@
+package ratelimit
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+	"sync"
+	"time"
+)
+
+const DefaultRefreshInterval = 30 * time.Second
+
+// List names one of the two access lists. Blocked principals are refused
+// outright; allowed principals skip the rate limit.
+type List string
+
+const (
+	Blocklist List = "blocklist"
+	Allowlist List = "allowlist"
+)
+
+func (l List) other() List {
+	if l == Blocklist {
+		return Allowlist
+	}
+	return Blocklist
+}
+
+// Store keeps the lists where every instance can read them.
+type Store interface {
+	Members(ctx context.Context, list List) ([]string, error)
+	Add(ctx context.Context, list List, principal string) error
+	Remove(ctx context.Context, list List, principal string) error
+}
+
+type Access int
+
+const (
+	Unlisted Access = iota
+	Allowed
+	Blocked
+)
+
+// AccessList answers lookups from memory so the rate limiter never waits on
+// the store. Changes made through it apply at once on this instance; other
+// instances pick them up on their next refresh.
+type AccessList struct {
+	store    Store
+	interval time.Duration
+	logger   *slog.Logger
+
+	mu      sync.RWMutex
+	blocked map[string]bool
+	allowed map[string]bool
+
+	wg     sync.WaitGroup
+	cancel context.CancelFunc
+}
+
+// NewAccessList reads store every interval; zero or less means
+// DefaultRefreshInterval.
+func NewAccessList(store Store, interval time.Duration, logger *slog.Logger) *AccessList {
+	if store == nil || logger == nil {
+		panic("access list store and logger cannot be nil")
+	}
+	if interval <= 0 {
+		interval = DefaultRefreshInterval
+	}
+	return &AccessList{
+		store:    store,
+		interval: interval,
+		logger:   logger.With("component", "AccessList"),
+		blocked:  map[string]bool{},
+		allowed:  map[string]bool{},
+	}
+}
+
+// ValidatePrincipal accepts the client IP addresses the rate limiter keys
+// requests on.
+func ValidatePrincipal(principal string) error {
+	if net.ParseIP(principal) == nil {
+		return fmt.Errorf("%w: principal must be an IP address", apperrors.ErrInvalidArgument)
+	}
+	return nil
+}
+
+// Lookup tells how principal is listed. A principal on both lists, which
+// only a direct edit of the store can cause, counts as blocked.
+func (a *AccessList) Lookup(principal string) Access {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	switch {
+	case a.blocked[principal]:
+		return Blocked
+	case a.allowed[principal]:
+		return Allowed
+	default:
+		return Unlisted
+	}
+}
+
+// Entries returns both lists, sorted.
+func (a *AccessList) Entries() (blocked, allowed []string) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return sortedKeys(a.blocked), sortedKeys(a.allowed)
+}
+
+// Add puts principal on list and takes it off the other one.
+func (a *AccessList) Add(ctx context.Context, list List, principal string) error {
+	if err := ValidatePrincipal(principal); err != nil {
+		return err
+	}
+	if err := a.store.Remove(ctx, list.other(), principal); err != nil {
+		return fmt.Errorf("%w: removing %s from the %s: %v", apperrors.ErrUnavailable, principal, list.other(), err)
+	}
+	if err := a.store.Add(ctx, list, principal); err != nil {
+		return fmt.Errorf("%w: adding %s to the %s: %v", apperrors.ErrUnavailable, principal, list, err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.set(list.other()), principal)
+	a.set(list)[principal] = true
+	return nil
+}
+
+// Remove takes principal off list. Removing an unlisted principal is not an
+// error.
+func (a *AccessList) Remove(ctx context.Context, list List, principal string) error {
+	if err := ValidatePrincipal(principal); err != nil {
+		return err
+	}
+	if err := a.store.Remove(ctx, list, principal); err != nil {
+		return fmt.Errorf("%w: removing %s from the %s: %v", apperrors.ErrUnavailable, principal, list, err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.set(list), principal)
+	return nil
+}
+
+// Refresh replaces both lists with what the store holds. On error the lists
+// in memory stay as they were.
+func (a *AccessList) Refresh(ctx context.Context) error {
+	blocked, err := a.store.Members(ctx, Blocklist)
+	if err != nil {
+		return fmt.Errorf("reading the blocklist: %w", err)
+	}
+	allowed, err := a.store.Members(ctx, Allowlist)
+	if err != nil {
+		return fmt.Errorf("reading the allowlist: %w", err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.blocked = toSet(blocked)
+	a.allowed = toSet(allowed)
+	return nil
+}
+
+// Start refreshes once right away and then every interval until Stop or
+// until ctx ends.
+func (a *AccessList) Start(ctx context.Context) {
+	loopCtx, cancel := context.WithCancel(ctx)
+	a.cancel = cancel
+
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		ticker := time.NewTicker(a.interval)
+		defer ticker.Stop()
+		for {
+			if err := a.Refresh(loopCtx); err != nil && loopCtx.Err() == nil {
+				a.logger.WarnContext(loopCtx, "Failed to refresh rate limit access lists", slog.Any("error", err))
+			}
+			select {
+			case <-loopCtx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (a *AccessList) Stop() {
+	if a.cancel == nil {
+		return
+	}
+	a.cancel()
+	a.wg.Wait()
+}
+
+func (a *AccessList) set(list List) map[string]bool {
+	if list == Blocklist {
+		return a.blocked
+	}
+	return a.allowed
+}
+
+func toSet(principals []string) map[string]bool {
+	set := make(map[string]bool, len(principals))
+	for _, p := range principals {
+		set[p] = true
+	}
+	return set
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,81 @@
+package ratelimit
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingStore struct{ *MemoryStore }
+
+func (*failingStore) Add(context.Context, List, string) error {
+	return errors.New("connection refused")
+}
+
+func (*failingStore) Members(context.Context, List) ([]string, error) {
+	return nil, errors.New("connection refused")
+}
+
+func newTestAccessList(store Store) *AccessList {
+	return NewAccessList(store, 0, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestAccessList(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("moves a principal between the lists", func(t *testing.T) {
+		store := NewMemoryStore()
+		a := newTestAccessList(store)
+
+		require.NoError(t, a.Add(ctx, Allowlist, "10.0.0.1"))
+		assert.Equal(t, Allowed, a.Lookup("10.0.0.1"))
+
+		require.NoError(t, a.Add(ctx, Blocklist, "10.0.0.1"))
+		assert.Equal(t, Blocked, a.Lookup("10.0.0.1"))
+		allowed, _ := store.Members(ctx, Allowlist)
+		assert.Empty(t, allowed, "blocking takes the principal off the allowlist in the store too")
+
+		require.NoError(t, a.Remove(ctx, Blocklist, "10.0.0.1"))
+		require.NoError(t, a.Remove(ctx, Blocklist, "10.0.0.1"), "removing twice is not an error")
+		assert.Equal(t, Unlisted, a.Lookup("10.0.0.1"))
+	})
+
+	t.Run("refresh picks up changes made by other instances", func(t *testing.T) {
+		store := NewMemoryStore()
+		a := newTestAccessList(store)
+		require.NoError(t, store.Add(ctx, Blocklist, "203.0.113.9"))
+		require.NoError(t, store.Add(ctx, Blocklist, "198.51.100.4"))
+		require.NoError(t, store.Add(ctx, Allowlist, "2001:db8::1"))
+
+		require.NoError(t, a.Refresh(ctx))
+
+		blocked, allowed := a.Entries()
+		assert.Equal(t, []string{"198.51.100.4", "203.0.113.9"}, blocked)
+		assert.Equal(t, []string{"2001:db8::1"}, allowed)
+	})
+
+	t.Run("rejects principals that are not IP addresses", func(t *testing.T) {
+		a := newTestAccessList(NewMemoryStore())
+
+		err := a.Add(ctx, Blocklist, "alice")
+
+		assert.ErrorIs(t, err, apperrors.ErrInvalidArgument)
+	})
+
+	t.Run("keeps the lists when the store fails", func(t *testing.T) {
+		a := newTestAccessList(&failingStore{NewMemoryStore()})
+		a.blocked["10.0.0.2"] = true
+
+		assert.ErrorIs(t, a.Add(ctx, Blocklist, "10.0.0.3"), apperrors.ErrUnavailable)
+		assert.Error(t, a.Refresh(ctx))
+
+		assert.Equal(t, Blocked, a.Lookup("10.0.0.2"))
+		assert.Equal(t, Unlisted, a.Lookup("10.0.0.3"))
+	})
+}
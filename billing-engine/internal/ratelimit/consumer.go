@@ -0,0 +1,16 @@
+package ratelimit
+
+import "time"
+
+// Consumer is what the rate limiter has counted for one principal since it
+// was last idle.
+type Consumer struct {
+	Principal string
+	Allowed   uint64
+	Limited   uint64
+	LastSeen  time.Time
+}
+
+func (c Consumer) Requests() uint64 {
+	return c.Allowed + c.Limited
+}
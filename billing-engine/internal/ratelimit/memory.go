@@ -0,0 +1,39 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryStore keeps the lists in process, for deployments without Redis.
+// Each instance then has lists of its own and loses them on restart.
+type MemoryStore struct {
+	mu    sync.Mutex
+	lists map[List]map[string]bool
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{lists: map[List]map[string]bool{Blocklist: {}, Allowlist: {}}}
+}
+
+func (s *MemoryStore) Members(_ context.Context, list List) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return sortedKeys(s.lists[list]), nil
+}
+
+func (s *MemoryStore) Add(_ context.Context, list List, principal string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lists[list][principal] = true
+	return nil
+}
+
+func (s *MemoryStore) Remove(_ context.Context, list List, principal string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.lists[list], principal)
+	return nil
+}
@@ -0,0 +1,96 @@
+// Package sandbox lets operators of a test deployment move the billing clock
+// forward. Every simulated day replays the daily batch jobs, so weeks of
+// schedule logic, delinquency and snapshots can be accepted in minutes.
+package sandbox
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// MaxAdvanceDays caps a single advance so one request cannot replay the jobs
+// for years.
+const MaxAdvanceDays = 366
+
+const day = 24 * time.Hour
+
+// Job is a daily batch job replayed for every simulated day, in the order the
+// jobs were given.
+type Job struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// State is where the billing clock stands.
+type State struct {
+	Now        time.Time
+	OffsetDays int
+}
+
+type Service interface {
+	State(ctx context.Context) State
+	// Advance moves the billing clock forward one day at a time and runs the
+	// jobs after each step. When a job fails the clock stays on the day that
+	// failed and the returned state says so.
+	Advance(ctx context.Context, days int) (State, error)
+}
+
+var _ Service = (*service)(nil)
+
+type service struct {
+	clock  *clock.Offset
+	jobs   []Job
+	mu     sync.Mutex
+	logger *slog.Logger
+}
+
+// NewService builds the sandbox on clk, which must be the clock the services,
+// repositories and jobs were built with.
+func NewService(clk *clock.Offset, jobs []Job, logger *slog.Logger) Service {
+	if clk == nil || logger == nil {
+		panic("sandbox dependencies cannot be nil")
+	}
+	return &service{
+		clock:  clk,
+		jobs:   jobs,
+		logger: logger.With(slog.String("component", "sandboxService")),
+	}
+}
+
+func (s *service) State(context.Context) State {
+	return State{Now: s.clock.Now(), OffsetDays: int(s.clock.Offset() / day)}
+}
+
+func (s *service) Advance(ctx context.Context, days int) (State, error) {
+	if days < 1 || days > MaxAdvanceDays {
+		return s.State(ctx), fmt.Errorf("%w: days must be between 1 and %d", apperrors.ErrInvalidArgument, MaxAdvanceDays)
+	}
+
+	// Advances run one after another so that the days of two requests do not
+	// interleave.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.logger.WarnContext(ctx, "Advancing billing clock", slog.Int("days", days), slog.Time("from", s.clock.Now()))
+	for i := 0; i < days; i++ {
+		if err := ctx.Err(); err != nil {
+			return s.State(ctx), err
+		}
+		s.clock.Advance(day)
+		for _, job := range s.jobs {
+			if err := job.Run(ctx); err != nil {
+				s.logger.ErrorContext(ctx, "Sandbox job failed", slog.String("job", job.Name), slog.Time("billingTime", s.clock.Now()), slog.Any("error", err))
+				return s.State(ctx), fmt.Errorf("%s job failed on %s: %w", job.Name, s.clock.Now().Format(time.DateOnly), err)
+			}
+		}
+	}
+
+	state := s.State(ctx)
+	s.logger.InfoContext(ctx, "Billing clock advanced", slog.Time("now", state.Now), slog.Int("offsetDays", state.OffsetDays))
+	return state, nil
+}
@@ -0,0 +1,90 @@
+package sandbox
+
+import (
+	"billing-engine/internal/pkg/apperrors"
+	"billing-engine/internal/pkg/clock"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+var start = time.Date(2025, 1, 6, 1, 0, 0, 0, time.UTC)
+
+// recordingJob remembers the billing time of every run.
+func recordingJob(name string, clk clock.Clock, runs *[]string) Job {
+	return Job{Name: name, Run: func(context.Context) error {
+		*runs = append(*runs, name+" "+clk.Now().Format(time.DateOnly))
+		return nil
+	}}
+}
+
+func TestServiceAdvanceRunsJobsForEveryDay(t *testing.T) {
+	clk := clock.NewOffset(clock.NewFake(start))
+	var runs []string
+	svc := NewService(clk, []Job{recordingJob("delinquency", clk, &runs), recordingJob("snapshot", clk, &runs)}, testLogger)
+
+	state, err := svc.Advance(context.Background(), 3)
+
+	require.NoError(t, err)
+	assert.Equal(t, start.AddDate(0, 0, 3), state.Now)
+	assert.Equal(t, 3, state.OffsetDays)
+	assert.Equal(t, []string{
+		"delinquency 2025-01-07", "snapshot 2025-01-07",
+		"delinquency 2025-01-08", "snapshot 2025-01-08",
+		"delinquency 2025-01-09", "snapshot 2025-01-09",
+	}, runs)
+	assert.Equal(t, state, svc.State(context.Background()))
+}
+
+func TestServiceAdvanceRejectsInvalidDays(t *testing.T) {
+	clk := clock.NewOffset(clock.NewFake(start))
+	svc := NewService(clk, nil, testLogger)
+
+	for _, days := range []int{0, -1, MaxAdvanceDays + 1} {
+		state, err := svc.Advance(context.Background(), days)
+		assert.ErrorIs(t, err, apperrors.ErrInvalidArgument, "days %d", days)
+		assert.Equal(t, 0, state.OffsetDays)
+	}
+}
+
+func TestServiceAdvanceStopsOnJobFailure(t *testing.T) {
+	clk := clock.NewOffset(clock.NewFake(start))
+	runs := 0
+	failing := Job{Name: "delinquency", Run: func(context.Context) error {
+		runs++
+		if runs == 2 {
+			return errors.New("database down")
+		}
+		return nil
+	}}
+	svc := NewService(clk, []Job{failing}, testLogger)
+
+	state, err := svc.Advance(context.Background(), 7)
+
+	assert.ErrorContains(t, err, "delinquency job failed on 2025-01-08")
+	assert.Equal(t, 2, state.OffsetDays, "the clock stays on the day that failed")
+	assert.Equal(t, 2, runs)
+}
+
+func TestServiceAdvanceStopsWhenCancelled(t *testing.T) {
+	clk := clock.NewOffset(clock.NewFake(start))
+	ctx, cancel := context.WithCancel(context.Background())
+	job := Job{Name: "snapshot", Run: func(context.Context) error {
+		cancel()
+		return nil
+	}}
+	svc := NewService(clk, []Job{job}, testLogger)
+
+	state, err := svc.Advance(ctx, 5)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, state.OffsetDays)
+}
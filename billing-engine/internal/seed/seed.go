@@ -0,0 +1,319 @@
+// Package seed fills a database with made-up customers and loans at every
+// stage of their life, for local development, demos and load tests.
+package seed
+
+import (
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Stage is where a generated customer's loan is in its life.
+type Stage string
+
+const (
+	// StageNone is a customer without a loan.
+	StageNone Stage = "none"
+	// StageNew is a loan whose first installment is not due yet.
+	StageNew Stage = "new"
+	// StageCurrent is a loan part way through its term with its installments
+	// paid, now and then one behind.
+	StageCurrent Stage = "current"
+	// StageDelinquent is a loan behind by at least the delinquency policy's
+	// missed payments, whose customer is flagged.
+	StageDelinquent Stage = "delinquent"
+	// StagePaidOff is a loan whose every installment is paid.
+	StagePaidOff Stage = "paid_off"
+)
+
+// Stages lists the stages in the order reports show them.
+var Stages = []Stage{StageNone, StageNew, StageCurrent, StageDelinquent, StagePaidOff}
+
+// Mix weighs how often each stage is generated. Stages left out are not
+// generated.
+type Mix map[Stage]int
+
+// DefaultMix is a book that has been lending for a while.
+func DefaultMix() Mix {
+	return Mix{StageNone: 10, StageNew: 15, StageCurrent: 45, StageDelinquent: 20, StagePaidOff: 10}
+}
+
+// ParseMix reads a mix written as comma separated stage=weight pairs, such
+// as "current=3,delinquent=1".
+func ParseMix(s string) (Mix, error) {
+	mix := Mix{}
+	for _, pair := range strings.Split(s, ",") {
+		name, weight, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		stage := Stage(strings.ToLower(strings.TrimSpace(name)))
+		if !ok || !slices.Contains(Stages, stage) {
+			return nil, fmt.Errorf("%w: %q is not a stage=weight pair; the stages are %s", apperrors.ErrInvalidArgument, pair, stageNames())
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(weight))
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("%w: the weight of %s must be a whole number of at least 0", apperrors.ErrInvalidArgument, stage)
+		}
+		mix[stage] = n
+	}
+	if mix.total() == 0 {
+		return nil, fmt.Errorf("%w: the mix must give some stage a weight", apperrors.ErrInvalidArgument)
+	}
+	return mix, nil
+}
+
+func stageNames() string {
+	names := make([]string, len(Stages))
+	for i, s := range Stages {
+		names[i] = string(s)
+	}
+	return strings.Join(names, ", ")
+}
+
+func (m Mix) total() int {
+	total := 0
+	for _, w := range m {
+		total += w
+	}
+	return total
+}
+
+func (m Mix) pick(rng *rand.Rand) Stage {
+	n := rng.IntN(m.total())
+	for _, stage := range Stages {
+		if n < m[stage] {
+			return stage
+		}
+		n -= m[stage]
+	}
+	panic("unreachable")
+}
+
+// Options says how much to generate. The same Seed and Now generate the same
+// customers and loans.
+type Options struct {
+	Customers int
+	Mix       Mix
+	Seed      uint64
+	// Now is the day the book is generated as of. Loans started before it,
+	// and their payments were made before it.
+	Now time.Time
+}
+
+// Report counts what Run created.
+type Report struct {
+	Customers int
+	Loans     map[Stage]int
+	Payments  int
+}
+
+// Generator writes customers, loans and payments through the repositories,
+// the way the services would have over the loans' lives. It publishes no
+// events.
+type Generator struct {
+	customers   customer.CustomerRepository
+	loans       loan.Repository
+	delinquency loan.DelinquencyPolicy
+	logger      *slog.Logger
+}
+
+// NewGenerator builds a generator. delinquency decides how far behind the
+// delinquent loans are and which customers are flagged.
+func NewGenerator(customers customer.CustomerRepository, loans loan.Repository, delinquency loan.DelinquencyPolicy, logger *slog.Logger) *Generator {
+	return &Generator{customers: customers, loans: loans, delinquency: delinquency, logger: logger}
+}
+
+// Run creates opts.Customers customers, one at a time, and calls onLoan with
+// each loan once it is complete. It stops at the first error, keeping what
+// was created so far, which the report counts.
+func (g *Generator) Run(ctx context.Context, opts Options, onLoan func(loanID int64, stage Stage) error) (*Report, error) {
+	if opts.Customers <= 0 {
+		return nil, fmt.Errorf("%w: the number of customers must be positive", apperrors.ErrInvalidArgument)
+	}
+	if opts.Mix.total() <= 0 {
+		return nil, fmt.Errorf("%w: the mix must give some stage a weight", apperrors.ErrInvalidArgument)
+	}
+	rng := rand.New(rand.NewPCG(opts.Seed, opts.Seed^0x5eed))
+	today := time.Date(opts.Now.Year(), opts.Now.Month(), opts.Now.Day(), 0, 0, 0, 0, time.UTC)
+
+	report := &Report{Loans: map[Stage]int{}}
+	for i := range opts.Customers {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		cust := customer.NewCustomer(randomName(rng), randomAddress(rng))
+		if err := g.customers.Save(ctx, cust); err != nil {
+			return report, fmt.Errorf("failed to create customer %d: %w", i+1, err)
+		}
+		report.Customers++
+
+		stage := opts.Mix.pick(rng)
+		if stage == StageNone {
+			continue
+		}
+		plan := g.plan(rng, stage, today)
+		loanID, payments, err := g.createLoan(ctx, rng, cust.CustomerID, plan, opts.Now)
+		report.Payments += payments
+		if err != nil {
+			return report, err
+		}
+		report.Loans[stage]++
+		if err := onLoan(loanID, stage); err != nil {
+			return report, err
+		}
+	}
+	g.logger.InfoContext(ctx, "Seed data generated", "customers", report.Customers, "payments", report.Payments)
+	return report, nil
+}
+
+// loanPlan is a loan of the stage to generate: its terms, when it started
+// and how many of its installments were paid.
+type loanPlan struct {
+	principal loan.Money
+	termWeeks int
+	rate      float64
+	start     time.Time
+	paid      int
+}
+
+var (
+	principals = []loan.Money{1_000_000, 2_500_000, 5_000_000, 5_000_000, 10_000_000, 20_000_000}
+	terms      = []int{25, 50, 50, 52, 100}
+	rates      = []float64{0.08, 0.10, 0.10, 0.12, 0.15}
+)
+
+func (g *Generator) plan(rng *rand.Rand, stage Stage, today time.Time) loanPlan {
+	p := loanPlan{
+		principal: principals[rng.IntN(len(principals))],
+		termWeeks: terms[rng.IntN(len(terms))],
+		rate:      rates[rng.IntN(len(rates))],
+	}
+
+	// elapsed installments are due before today; none falls on today, which
+	// would not count as missed yet.
+	var elapsed int
+	switch stage {
+	case StageNew:
+		p.start = today.AddDate(0, 0, -rng.IntN(7))
+		return p
+	case StageCurrent:
+		elapsed = 1 + rng.IntN(p.termWeeks-1)
+		p.paid = elapsed
+		if rng.IntN(5) == 0 {
+			p.paid--
+		}
+	case StageDelinquent:
+		behind := g.delinquency.MissedPayments + rng.IntN(4)
+		behind = min(behind, p.termWeeks)
+		elapsed = behind + rng.IntN(p.termWeeks-behind+1)
+		p.paid = elapsed - behind
+	case StagePaidOff:
+		elapsed = p.termWeeks + rng.IntN(27)
+		p.paid = p.termWeeks
+	}
+	p.start = today.AddDate(0, 0, -7*elapsed-1-rng.IntN(6))
+	return p
+}
+
+var channels = []loan.PaymentChannel{loan.ChannelBankTransfer, loan.ChannelBankTransfer, loan.ChannelGateway, loan.ChannelDirectDebit, loan.ChannelCash}
+
+// createLoan stores the planned loan, pays its first plan.paid installments
+// in one transaction and records its days past due and its customer's
+// delinquency as the nightly job would. It returns the loan ID and the number
+// of payments made.
+func (g *Generator) createLoan(ctx context.Context, rng *rand.Rand, customerID int64, plan loanPlan, now time.Time) (int64, int, error) {
+	newLoan, err := loan.NewLoan(plan.principal, plan.termWeeks, plan.rate, plan.start)
+	if err != nil {
+		return 0, 0, err
+	}
+	schedule, err := newLoan.GenerateSchedule()
+	if err != nil {
+		return 0, 0, err
+	}
+	created, err := g.loans.CreateLoan(ctx, customerID, newLoan, schedule)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to create the loan of customer %d: %w", customerID, err)
+	}
+	if plan.paid == 0 {
+		return created.ID, 0, g.recordDelinquency(ctx, created.ID, customerID, now)
+	}
+
+	err = g.loans.WithinTransaction(ctx, func(tx loan.TxRepository) error {
+		for range plan.paid {
+			entry, err := tx.FindOldestUnpaidEntryForUpdate(ctx, created.ID)
+			if err != nil {
+				return err
+			}
+			// Paid in the few days up to the due date, during the day.
+			paidAt := entry.DueDate.AddDate(0, 0, -rng.IntN(4)).Add(time.Duration(8+rng.IntN(12)) * time.Hour)
+			if paidAt.After(now) {
+				paidAt = now
+			}
+			entry.PaidAmount, entry.PaymentDate, entry.Status = entry.DueAmount, &paidAt, loan.PaymentStatusPaid
+			if err := tx.UpdateScheduleEntry(ctx, entry); err != nil {
+				return err
+			}
+			payment := &loan.Payment{LoanID: created.ID, ScheduleID: &entry.ID, Amount: entry.DueAmount,
+				Channel: channels[rng.IntN(len(channels))], PaidAt: paidAt}
+			reference := fmt.Sprintf("SEED-%d-%d", created.ID, entry.WeekNumber)
+			payment.Reference = &reference
+			if payment.Channel == loan.ChannelCash {
+				collector := fmt.Sprintf("collector-%02d", 1+rng.IntN(20))
+				payment.CollectorID = &collector
+			}
+			if err := tx.RecordPayment(ctx, payment); err != nil {
+				return err
+			}
+		}
+		if plan.paid == plan.termWeeks {
+			return tx.UpdateLoanStatus(ctx, created.ID, loan.StatusPaidOff)
+		}
+		return nil
+	})
+	if err != nil {
+		return created.ID, 0, fmt.Errorf("failed to pay loan %d: %w", created.ID, err)
+	}
+	return created.ID, plan.paid, g.recordDelinquency(ctx, created.ID, customerID, now)
+}
+
+func (g *Generator) recordDelinquency(ctx context.Context, loanID, customerID int64, now time.Time) error {
+	schedule, err := g.loans.GetScheduleByLoanID(ctx, loanID)
+	if err != nil {
+		return fmt.Errorf("failed to read the schedule of loan %d: %w", loanID, err)
+	}
+	if dpd := loan.DaysPastDue(schedule, now); dpd > 0 {
+		if err := g.loans.UpdateDaysPastDue(ctx, loanID, dpd); err != nil {
+			return fmt.Errorf("failed to store the days past due of loan %d: %w", loanID, err)
+		}
+	}
+	if g.delinquency.Evaluate(schedule, now, false) {
+		if err := g.customers.SetDelinquencyStatus(ctx, customerID, true); err != nil {
+			return fmt.Errorf("failed to flag customer %d: %w", customerID, err)
+		}
+	}
+	return nil
+}
+
+var (
+	firstNames = []string{"Adi", "Ayu", "Bima", "Citra", "Dewi", "Eko", "Fajar", "Gita", "Hadi", "Indah",
+		"Joko", "Kartika", "Lestari", "Made", "Nur", "Putri", "Rizky", "Sari", "Tono", "Wulan", "Yusuf"}
+	lastNames = []string{"Santoso", "Wijaya", "Hidayat", "Saputra", "Pratama", "Kusuma", "Lestari", "Nugroho",
+		"Siregar", "Nasution", "Wibowo", "Halim", "Gunawan", "Setiawan", "Purnomo"}
+	streets = []string{"Jl. Merdeka", "Jl. Sudirman", "Jl. Diponegoro", "Jl. Gatot Subroto", "Jl. Ahmad Yani",
+		"Jl. Pahlawan", "Jl. Kenanga", "Jl. Melati"}
+	cities = []string{"Jakarta", "Bandung", "Surabaya", "Medan", "Semarang", "Makassar", "Yogyakarta", "Denpasar"}
+)
+
+func randomName(rng *rand.Rand) string {
+	return firstNames[rng.IntN(len(firstNames))] + " " + lastNames[rng.IntN(len(lastNames))]
+}
+
+func randomAddress(rng *rand.Rand) string {
+	return fmt.Sprintf("%s No. %d, %s", streets[rng.IntN(len(streets))], 1+rng.IntN(200), cities[rng.IntN(len(cities))])
+}
@@ -0,0 +1,192 @@
+package seed
+
+import (
+	"billing-engine/internal/domain/customer"
+	"billing-engine/internal/domain/loan"
+	"billing-engine/internal/pkg/apperrors"
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCustomers keeps the customers in memory. The embedded interface is nil:
+// the generator calls nothing else.
+type fakeCustomers struct {
+	customer.CustomerRepository
+	saved      []*customer.Customer
+	delinquent map[int64]bool
+}
+
+func (f *fakeCustomers) Save(_ context.Context, c *customer.Customer) error {
+	c.CustomerID = int64(len(f.saved) + 1)
+	f.saved = append(f.saved, c)
+	return nil
+}
+
+func (f *fakeCustomers) SetDelinquencyStatus(_ context.Context, id int64, isDelinquent bool) error {
+	f.delinquent[id] = isDelinquent
+	return nil
+}
+
+// fakeLoans keeps the loans, their schedules and payments in memory.
+type fakeLoans struct {
+	loan.Repository
+	loans     map[int64]*loan.Loan
+	schedules map[int64][]loan.ScheduleEntry
+	payments  []*loan.Payment
+	dpd       map[int64]int
+}
+
+func newFakeLoans() *fakeLoans {
+	return &fakeLoans{loans: map[int64]*loan.Loan{}, schedules: map[int64][]loan.ScheduleEntry{}, dpd: map[int64]int{}}
+}
+
+func (f *fakeLoans) CreateLoan(_ context.Context, _ int64, l *loan.Loan, schedule []loan.ScheduleEntry) (*loan.Loan, error) {
+	l.ID = int64(len(f.loans) + 1)
+	for i := range schedule {
+		schedule[i].ID, schedule[i].LoanID = l.ID*1000+int64(i), l.ID
+	}
+	f.loans[l.ID], f.schedules[l.ID] = l, schedule
+	return l, nil
+}
+
+func (f *fakeLoans) WithinTransaction(ctx context.Context, fn func(tx loan.TxRepository) error) error {
+	return fn(fakeTx{fakeLoans: f})
+}
+
+// fakeTx writes straight to the fake repository; the generator never rolls
+// back.
+type fakeTx struct {
+	loan.TxRepository
+	*fakeLoans
+}
+
+func (f fakeTx) FindOldestUnpaidEntryForUpdate(_ context.Context, loanID int64) (*loan.ScheduleEntry, error) {
+	for _, e := range f.schedules[loanID] {
+		if e.Status != loan.PaymentStatusPaid {
+			return &e, nil
+		}
+	}
+	return nil, apperrors.ErrNotFound
+}
+
+func (f fakeTx) UpdateScheduleEntry(_ context.Context, entry *loan.ScheduleEntry) error {
+	schedule := f.schedules[entry.LoanID]
+	schedule[entry.WeekNumber-1] = *entry
+	return nil
+}
+
+func (f fakeTx) RecordPayment(_ context.Context, p *loan.Payment) error {
+	f.payments = append(f.payments, p)
+	return nil
+}
+
+func (f fakeTx) UpdateLoanStatus(_ context.Context, loanID int64, status loan.LoanStatus) error {
+	f.loans[loanID].Status = status
+	return nil
+}
+
+func (f *fakeLoans) GetScheduleByLoanID(_ context.Context, loanID int64) ([]loan.ScheduleEntry, error) {
+	return f.schedules[loanID], nil
+}
+
+func (f *fakeLoans) UpdateDaysPastDue(_ context.Context, loanID int64, dpd int) error {
+	f.dpd[loanID] = dpd
+	return nil
+}
+
+func TestParseMix(t *testing.T) {
+	mix, err := ParseMix("current=3, Delinquent=1,none=0")
+	require.NoError(t, err)
+	assert.Equal(t, Mix{StageCurrent: 3, StageDelinquent: 1, StageNone: 0}, mix)
+
+	for _, bad := range []string{"", "current", "overdue=1", "current=-1", "current=x", "current=0,new=0"} {
+		_, err := ParseMix(bad)
+		assert.ErrorIs(t, err, apperrors.ErrInvalidArgument, bad)
+	}
+}
+
+func TestGeneratorRun(t *testing.T) {
+	now := time.Date(2025, 6, 18, 10, 0, 0, 0, time.UTC)
+	policy := loan.DefaultDelinquencyPolicy()
+	customers, loans := &fakeCustomers{delinquent: map[int64]bool{}}, newFakeLoans()
+	generator := NewGenerator(customers, loans, policy, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	stages := map[int64]Stage{}
+	report, err := generator.Run(context.Background(), Options{Customers: 200, Mix: DefaultMix(), Seed: 7, Now: now},
+		func(loanID int64, stage Stage) error {
+			stages[loanID] = stage
+			return nil
+		})
+	require.NoError(t, err)
+
+	assert.Equal(t, 200, report.Customers)
+	assert.Len(t, customers.saved, 200)
+	assert.Len(t, loans.payments, report.Payments)
+	assert.Len(t, stages, len(loans.loans))
+	for _, stage := range Stages[1:] {
+		assert.Positive(t, report.Loans[stage], stage)
+	}
+
+	for loanID, stage := range stages {
+		l, schedule := loans.loans[loanID], loans.schedules[loanID]
+		assert.Falsef(t, l.StartDate.After(now), "loan %d starts after now", loanID)
+		behind := 0
+		for _, e := range schedule {
+			if e.Status == loan.PaymentStatusPaid {
+				assert.False(t, e.PaymentDate.After(now))
+			} else if e.DueDate.Before(now) {
+				behind++
+			}
+		}
+		switch stage {
+		case StageNew:
+			assert.Zero(t, behind)
+			assert.Zero(t, loans.dpd[loanID])
+		case StageCurrent:
+			assert.LessOrEqual(t, behind, 1)
+		case StageDelinquent:
+			assert.GreaterOrEqual(t, behind, policy.MissedPayments)
+			assert.Positive(t, loans.dpd[loanID])
+			assert.True(t, policy.Evaluate(schedule, now, false))
+		case StagePaidOff:
+			assert.Zero(t, behind)
+			assert.Equal(t, loan.StatusPaidOff, l.Status)
+		}
+	}
+
+	flagged := 0
+	for _, isDelinquent := range customers.delinquent {
+		if isDelinquent {
+			flagged++
+		}
+	}
+	assert.Equal(t, report.Loans[StageDelinquent], flagged)
+}
+
+func TestGeneratorRunIsReproducible(t *testing.T) {
+	type terms struct {
+		principal float64
+		weeks     int
+		start     time.Time
+	}
+	run := func() []terms {
+		loans := newFakeLoans()
+		generator := NewGenerator(&fakeCustomers{delinquent: map[int64]bool{}}, loans, loan.DefaultDelinquencyPolicy(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+		_, err := generator.Run(context.Background(), Options{Customers: 30, Mix: DefaultMix(), Seed: 42, Now: time.Date(2025, 6, 18, 0, 0, 0, 0, time.UTC)},
+			func(int64, Stage) error { return nil })
+		require.NoError(t, err)
+		var generated []terms
+		for id := int64(1); id <= int64(len(loans.loans)); id++ {
+			l := loans.loans[id]
+			generated = append(generated, terms{l.PrincipalAmount, l.TermWeeks, l.StartDate})
+		}
+		return generated
+	}
+	assert.Equal(t, run(), run())
+}
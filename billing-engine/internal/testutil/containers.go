@@ -0,0 +1,221 @@
+//go:build integration
+
+package testutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/testcontainers/testcontainers-go"
+	tcpostgres "github.com/testcontainers/testcontainers-go/modules/postgres"
+	tcrabbitmq "github.com/testcontainers/testcontainers-go/modules/rabbitmq"
+)
+
+const (
+	postgresImage = "postgres:16-alpine"
+	rabbitMQImage = "rabbitmq:3.13-management-alpine"
+
+	startTimeout = 2 * time.Minute
+)
+
+// DockerAvailable reports why containers cannot be started, or nil when a
+// Docker daemon answers.
+func DockerAvailable(ctx context.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("docker provider panicked: %v", r)
+		}
+	}()
+	provider, err := testcontainers.ProviderDocker.GetProvider()
+	if err != nil {
+		return err
+	}
+	defer provider.Close()
+	return provider.Health(ctx)
+}
+
+// Postgres is a migrated database in a throwaway container.
+type Postgres struct {
+	URL  string
+	Pool *pgxpool.Pool
+
+	container *tcpostgres.PostgresContainer
+}
+
+// StartPostgres starts PostgreSQL and applies the numbered migrations.
+func StartPostgres(ctx context.Context) (*Postgres, error) {
+	ctx, cancel := context.WithTimeout(ctx, startTimeout)
+	defer cancel()
+
+	container, err := tcpostgres.Run(ctx, postgresImage,
+		tcpostgres.WithDatabase("billing_db"),
+		tcpostgres.WithUsername("user"),
+		tcpostgres.WithPassword("password"),
+		tcpostgres.BasicWaitStrategies(),
+	)
+	pg := &Postgres{container: container}
+	if err != nil {
+		pg.Close(context.Background())
+		return nil, fmt.Errorf("failed to start postgres: %w", err)
+	}
+
+	if pg.URL, err = container.ConnectionString(ctx, "sslmode=disable"); err != nil {
+		pg.Close(context.Background())
+		return nil, err
+	}
+	if pg.Pool, err = pgxpool.New(ctx, pg.URL); err != nil {
+		pg.Close(context.Background())
+		return nil, err
+	}
+	if err := pg.migrate(ctx); err != nil {
+		pg.Close(context.Background())
+		return nil, err
+	}
+	return pg, nil
+}
+
+// migrate runs each up section as one simple protocol batch, which is what
+// lets the plpgsql bodies and multiple statements through.
+func (p *Postgres) migrate(ctx context.Context) error {
+	migrations, err := LoadMigrations(MigrationsDir())
+	if err != nil {
+		return err
+	}
+	for _, m := range migrations {
+		if _, err := p.Pool.Exec(ctx, m.Up); err != nil {
+			return fmt.Errorf("failed to apply migration %s: %w", m.Name, err)
+		}
+	}
+	return nil
+}
+
+// Reset empties every table and restarts the identity sequences so each test
+// starts from a freshly migrated database.
+func (p *Postgres) Reset(ctx context.Context) error {
+	_, err := p.Pool.Exec(ctx, `
+        DO $$
+        DECLARE
+            tables TEXT;
+        BEGIN
+            SELECT string_agg(format('%I.%I', schemaname, tablename), ', ')
+            INTO tables
+            FROM pg_tables
+            WHERE schemaname = 'public';
+            IF tables IS NOT NULL THEN
+                EXECUTE 'TRUNCATE ' || tables || ' RESTART IDENTITY CASCADE';
+            END IF;
+        END
+        $$`)
+	if err != nil {
+		return fmt.Errorf("failed to reset database: %w", err)
+	}
+	return nil
+}
+
+func (p *Postgres) Close(ctx context.Context) error {
+	if p.Pool != nil {
+		p.Pool.Close()
+	}
+	return testcontainers.TerminateContainer(p.container, testcontainers.StopContext(ctx))
+}
+
+// RabbitMQ is a broker in a throwaway container.
+type RabbitMQ struct {
+	URL  string
+	Conn *amqp.Connection
+
+	container *tcrabbitmq.RabbitMQContainer
+}
+
+func StartRabbitMQ(ctx context.Context) (*RabbitMQ, error) {
+	ctx, cancel := context.WithTimeout(ctx, startTimeout)
+	defer cancel()
+
+	container, err := tcrabbitmq.Run(ctx, rabbitMQImage)
+	mq := &RabbitMQ{container: container}
+	if err != nil {
+		mq.Close(context.Background())
+		return nil, fmt.Errorf("failed to start rabbitmq: %w", err)
+	}
+
+	if mq.URL, err = container.AmqpURL(ctx); err != nil {
+		mq.Close(context.Background())
+		return nil, err
+	}
+	if mq.Conn, err = amqp.Dial(mq.URL); err != nil {
+		mq.Close(context.Background())
+		return nil, fmt.Errorf("failed to connect to rabbitmq: %w", err)
+	}
+	return mq, nil
+}
+
+// Subscribe binds a new exclusive queue to exchange with routingKey and
+// returns its deliveries. The exchange is declared the way the publisher
+// declares it, so subscribing first is fine. The channel closes with ctx.
+func (r *RabbitMQ) Subscribe(ctx context.Context, exchange, routingKey string) (<-chan amqp.Delivery, error) {
+	ch, err := r.Conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+	if err := ch.ExchangeDeclare(exchange, amqp.ExchangeTopic, true, false, false, false, nil); err != nil {
+		ch.Close()
+		return nil, err
+	}
+	q, err := ch.QueueDeclare("", false, true, true, false, nil)
+	if err != nil {
+		ch.Close()
+		return nil, err
+	}
+	if err := ch.QueueBind(q.Name, routingKey, exchange, false, nil); err != nil {
+		ch.Close()
+		return nil, err
+	}
+	deliveries, err := ch.Consume(q.Name, "", true, true, false, false, nil)
+	if err != nil {
+		ch.Close()
+		return nil, err
+	}
+	go func() {
+		<-ctx.Done()
+		ch.Close()
+	}()
+	return deliveries, nil
+}
+
+func (r *RabbitMQ) Close(ctx context.Context) error {
+	var errs []error
+	if r.Conn != nil && !r.Conn.IsClosed() {
+		errs = append(errs, r.Conn.Close())
+	}
+	errs = append(errs, testcontainers.TerminateContainer(r.container, testcontainers.StopContext(ctx)))
+	return errors.Join(errs...)
+}
+
+// Env is the set of containers an integration test package shares.
+type Env struct {
+	Postgres *Postgres
+	RabbitMQ *RabbitMQ
+}
+
+// Start brings up every container. Packages call it once from TestMain and
+// Reset the database between tests.
+func Start(ctx context.Context) (*Env, error) {
+	pg, err := StartPostgres(ctx)
+	if err != nil {
+		return nil, err
+	}
+	mq, err := StartRabbitMQ(ctx)
+	if err != nil {
+		pg.Close(ctx)
+		return nil, err
+	}
+	return &Env{Postgres: pg, RabbitMQ: mq}, nil
+}
+
+func (e *Env) Close(ctx context.Context) error {
+	return errors.Join(e.RabbitMQ.Close(ctx), e.Postgres.Close(ctx))
+}
@@ -0,0 +1,6 @@
+// Package testutil starts the services the billing engine depends on in
+// throwaway containers for the integration tests. The container helpers need
+// Docker and are only compiled with the integration build tag:
+//
+//	go test -tags integration ./internal/integration/...
+package testutil
@@ -0,0 +1,30 @@
+package testutil
+
+import (
+	"billing-engine/migrations"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// Migration is the up section of one numbered file in migrations/.
+type Migration = migrations.Migration
+
+// MigrationsDir returns the migrations directory of this module, found
+// relative to this source file so tests can run from any package.
+func MigrationsDir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", "migrations")
+}
+
+// LoadMigrations reads the numbered migrations in dir in file name order.
+// init.sql, the combined copy docker compose mounts, is skipped so the
+// numbered files are what the tests run against.
+func LoadMigrations(dir string) ([]Migration, error) {
+	loaded, err := migrations.Load(os.DirFS(dir))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", dir, err)
+	}
+	return loaded, nil
+}
@@ -0,0 +1,31 @@
+package testutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := LoadMigrations(MigrationsDir())
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+
+	assert.Equal(t, "001_create_loans_table.sql", migrations[0].Name)
+	for _, m := range migrations {
+		assert.NotEqual(t, "init.sql", m.Name)
+		assert.NotContains(t, m.Up, "-- +migrate Down", m.Name)
+		assert.NotContains(t, m.Up, "DROP TABLE", "%s: the down section leaked into up", m.Name)
+	}
+}
+
+func TestLoadMigrationsEmptyDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "init.sql"), []byte("SELECT 1;"), 0o600))
+
+	_, err := LoadMigrations(dir)
+	assert.ErrorContains(t, err, "no migrations")
+}
@@ -0,0 +1,109 @@
+// k6 load profile for the money paths: loan reads, outstanding balances and
+// payments. Seed a scratch database first and pass the IDs it prints:
+//
+//   ./bin/billing-engine seed -customers 2000 -mix new=1,current=3,delinquent=1 -out loadtest/loans.txt
+//   k6 run -e BASE_URL=http://localhost:8080 loadtest/money-paths.js
+//
+// The rates, the duration and the thresholds can be changed with -e, see the
+// constants below. k6 exits non-zero when a threshold fails, which is what
+// the release check looks at.
+import http from 'k6/http';
+import { check, fail } from 'k6';
+import { SharedArray } from 'k6/data';
+import exec from 'k6/execution';
+
+const BASE_URL = __ENV.BASE_URL || 'http://localhost:8080';
+const DURATION = __ENV.DURATION || '2m';
+const READ_RATE = Number(__ENV.READ_RATE || 100);
+const PAYMENT_RATE = Number(__ENV.PAYMENT_RATE || 20);
+
+const loanIDs = new SharedArray('loans', () =>
+  open(__ENV.LOAN_IDS || './loans.txt').split('\n').map((s) => s.trim()).filter(Boolean));
+
+// A payment that finds another one for the same loan in progress is refused
+// with 409 by design; that is not a failed request.
+const paymentStatuses = http.expectedStatuses(200, 409);
+
+export const options = {
+  scenarios: {
+    loan_reads: {
+      executor: 'constant-arrival-rate',
+      exec: 'readLoan',
+      rate: READ_RATE,
+      timeUnit: '1s',
+      duration: DURATION,
+      preAllocatedVUs: Math.ceil(READ_RATE / 2),
+      maxVUs: READ_RATE * 2,
+    },
+    payments: {
+      executor: 'constant-arrival-rate',
+      exec: 'payInstallment',
+      rate: PAYMENT_RATE,
+      timeUnit: '1s',
+      duration: DURATION,
+      preAllocatedVUs: PAYMENT_RATE,
+      maxVUs: PAYMENT_RATE * 4,
+    },
+  },
+  thresholds: {
+    'http_req_failed': ['rate<0.01'],
+    'http_req_duration{endpoint:loan}': [`p(95)<${__ENV.LOAN_P95 || 150}`],
+    'http_req_duration{endpoint:loan_schedule}': [`p(95)<${__ENV.LOAN_SCHEDULE_P95 || 250}`],
+    'http_req_duration{endpoint:outstanding}': [`p(95)<${__ENV.OUTSTANDING_P95 || 150}`],
+    'http_req_duration{endpoint:payment}': [`p(95)<${__ENV.PAYMENT_P95 || 300}`],
+    'checks': ['rate>0.99'],
+  },
+};
+
+export function setup() {
+  if (loanIDs.length === 0) {
+    fail('no loan IDs: run billing-engine seed and pass the file in LOAN_IDS');
+  }
+  const res = http.post(`${BASE_URL}/v1/auth/token`, JSON.stringify({ username: 'loadtest' }), {
+    headers: { 'Content-Type': 'application/json' },
+  });
+  if (res.status !== 200) {
+    fail(`could not get a token: ${res.status} ${res.body}`);
+  }
+  return { authorization: res.json('token') };
+}
+
+function headers(data) {
+  return { Authorization: data.authorization, 'Content-Type': 'application/json' };
+}
+
+function randomLoan() {
+  return loanIDs[Math.floor(Math.random() * loanIDs.length)];
+}
+
+export function readLoan(data) {
+  const id = randomLoan();
+  const roll = Math.random();
+  let res;
+  if (roll < 0.5) {
+    res = http.get(`${BASE_URL}/v1/loans/${id}`, { headers: headers(data), tags: { endpoint: 'loan' } });
+  } else if (roll < 0.7) {
+    res = http.get(`${BASE_URL}/v1/loans/${id}?include=schedule`, { headers: headers(data), tags: { endpoint: 'loan_schedule' } });
+  } else {
+    res = http.get(`${BASE_URL}/v1/loans/${id}/outstanding`, { headers: headers(data), tags: { endpoint: 'outstanding' } });
+  }
+  check(res, { 'read succeeded': (r) => r.status === 200 });
+}
+
+// payInstallment pays the next installment of the loans in turn, so that
+// concurrent payments rarely meet on one loan.
+export function payInstallment(data) {
+  const id = loanIDs[exec.scenario.iterationInTest % loanIDs.length];
+  const loan = http.get(`${BASE_URL}/v1/loans/${id}`, { headers: headers(data), tags: { endpoint: 'loan' } });
+  if (!check(loan, { 'loan read before payment': (r) => r.status === 200 })) {
+    return;
+  }
+  const amount = loan.json('nextDueAmount');
+  if (!amount) {
+    return; // paid off
+  }
+  const res = http.post(`${BASE_URL}/v1/loans/${id}/payments`,
+    JSON.stringify({ amount, channel: 'BANK_TRANSFER', reference: `LT-${id}-${exec.scenario.iterationInTest}-${Date.now()}` }),
+    { headers: headers(data), tags: { endpoint: 'payment' }, responseCallback: paymentStatuses });
+  check(res, { 'payment accepted or refused as concurrent': (r) => r.status === 200 || r.status === 409 });
+}
@@ -0,0 +1,12 @@
+-- +migrate Up
+
+-- Reference assigned to the customer by the upstream system it was imported from
+ALTER TABLE customers ADD COLUMN external_ref VARCHAR(64) NULL;
+
+-- Imports deduplicate on this reference (allows multiple NULLs)
+ALTER TABLE customers ADD CONSTRAINT uq_customers_external_ref UNIQUE (external_ref);
+
+-- +migrate Down
+
+ALTER TABLE customers DROP CONSTRAINT IF EXISTS uq_customers_external_ref;
+ALTER TABLE customers DROP COLUMN IF EXISTS external_ref;
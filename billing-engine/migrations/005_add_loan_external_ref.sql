@@ -0,0 +1,12 @@
+-- +migrate Up
+
+-- Reference assigned to the loan by the integrating system
+ALTER TABLE loans ADD COLUMN external_ref VARCHAR(64) NULL;
+
+-- Ensure a reference identifies at most one loan (allows multiple NULLs)
+ALTER TABLE loans ADD CONSTRAINT uq_loans_external_ref UNIQUE (external_ref);
+
+-- +migrate Down
+
+ALTER TABLE loans DROP CONSTRAINT IF EXISTS uq_loans_external_ref;
+ALTER TABLE loans DROP COLUMN IF EXISTS external_ref;
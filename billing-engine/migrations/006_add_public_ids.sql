@@ -0,0 +1,17 @@
+-- +migrate Up
+
+-- Public identifiers exposed by the API; the bigint keys stay internal.
+-- Existing rows are backfilled by the column default.
+ALTER TABLE customers ADD COLUMN public_id UUID NOT NULL DEFAULT gen_random_uuid();
+ALTER TABLE customers ADD CONSTRAINT uq_customers_public_id UNIQUE (public_id);
+
+ALTER TABLE loans ADD COLUMN public_id UUID NOT NULL DEFAULT gen_random_uuid();
+ALTER TABLE loans ADD CONSTRAINT uq_loans_public_id UNIQUE (public_id);
+
+-- +migrate Down
+
+ALTER TABLE loans DROP CONSTRAINT IF EXISTS uq_loans_public_id;
+ALTER TABLE loans DROP COLUMN IF EXISTS public_id;
+
+ALTER TABLE customers DROP CONSTRAINT IF EXISTS uq_customers_public_id;
+ALTER TABLE customers DROP COLUMN IF EXISTS public_id;
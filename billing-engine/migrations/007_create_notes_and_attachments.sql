@@ -0,0 +1,38 @@
+-- +migrate Up
+
+-- Free-text notes recorded by staff against exactly one loan or customer
+CREATE TABLE notes (
+    id BIGSERIAL PRIMARY KEY,
+    loan_id BIGINT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    customer_id BIGINT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    author VARCHAR(255) NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_notes_subject CHECK ((loan_id IS NULL) <> (customer_id IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_notes_loan_id ON notes (loan_id) WHERE loan_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_notes_customer_id ON notes (customer_id) WHERE customer_id IS NOT NULL;
+
+-- Metadata of files kept in object storage; storage_key locates the object
+CREATE TABLE attachments (
+    id BIGSERIAL PRIMARY KEY,
+    loan_id BIGINT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    customer_id BIGINT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    file_name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    size_bytes BIGINT NOT NULL CHECK (size_bytes >= 0),
+    storage_key VARCHAR(512) NOT NULL,
+    uploaded_by VARCHAR(255) NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT uq_attachments_storage_key UNIQUE (storage_key),
+    CONSTRAINT chk_attachments_subject CHECK ((loan_id IS NULL) <> (customer_id IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachments_loan_id ON attachments (loan_id) WHERE loan_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_attachments_customer_id ON attachments (customer_id) WHERE customer_id IS NOT NULL;
+
+-- +migrate Down
+
+DROP TABLE IF EXISTS attachments;
+DROP TABLE IF EXISTS notes;
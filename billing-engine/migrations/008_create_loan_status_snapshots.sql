@@ -0,0 +1,19 @@
+-- +migrate Up
+
+-- One row per loan per day, written by the snapshot job, so the book can be
+-- reported as it stood on a past date
+CREATE TABLE loan_status_snapshots (
+    snapshot_date DATE NOT NULL,
+    loan_id BIGINT NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('ACTIVE', 'PAID_OFF', 'DELINQUENT')),
+    outstanding DECIMAL(15, 2) NOT NULL CHECK (outstanding >= 0),
+    dpd INT NOT NULL DEFAULT 0 CHECK (dpd >= 0), -- days past due of the oldest unpaid installment
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (loan_id, snapshot_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_loan_status_snapshots_date ON loan_status_snapshots (snapshot_date);
+
+-- +migrate Down
+
+DROP TABLE IF EXISTS loan_status_snapshots;
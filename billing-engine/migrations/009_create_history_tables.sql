@@ -0,0 +1,88 @@
+-- +migrate Up
+
+-- Row versions of loans and loan_schedule, kept by triggers so that every
+-- write is captured, including ones made outside the application. Each row
+-- holds the full record as JSONB and is valid from valid_from until valid_to;
+-- the current version has valid_to NULL. clock_timestamp() keeps several
+-- changes within one transaction in order.
+CREATE TABLE loans_history (
+    history_id BIGSERIAL PRIMARY KEY,
+    loan_id BIGINT NOT NULL,
+    operation CHAR(1) NOT NULL CHECK (operation IN ('I', 'U')),
+    row_data JSONB NOT NULL,
+    valid_from TIMESTAMPTZ NOT NULL,
+    valid_to TIMESTAMPTZ NULL
+);
+CREATE INDEX idx_loans_history_loan_id_valid_from ON loans_history (loan_id, valid_from);
+
+CREATE TABLE loan_schedule_history (
+    history_id BIGSERIAL PRIMARY KEY,
+    schedule_id BIGINT NOT NULL,
+    loan_id BIGINT NOT NULL,
+    operation CHAR(1) NOT NULL CHECK (operation IN ('I', 'U')),
+    row_data JSONB NOT NULL,
+    valid_from TIMESTAMPTZ NOT NULL,
+    valid_to TIMESTAMPTZ NULL
+);
+CREATE INDEX idx_loan_schedule_history_loan_id_valid_from ON loan_schedule_history (loan_id, valid_from);
+CREATE INDEX idx_loan_schedule_history_schedule_id ON loan_schedule_history (schedule_id) WHERE valid_to IS NULL;
+
+CREATE OR REPLACE FUNCTION record_loans_history()
+RETURNS TRIGGER AS $$
+DECLARE
+  changed_at TIMESTAMPTZ := clock_timestamp();
+BEGIN
+  IF TG_OP IN ('UPDATE', 'DELETE') THEN
+    UPDATE loans_history SET valid_to = changed_at
+    WHERE loan_id = OLD.id AND valid_to IS NULL;
+  END IF;
+  IF TG_OP IN ('INSERT', 'UPDATE') THEN
+    INSERT INTO loans_history (loan_id, operation, row_data, valid_from)
+    VALUES (NEW.id, LEFT(TG_OP, 1), to_jsonb(NEW), changed_at);
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION record_loan_schedule_history()
+RETURNS TRIGGER AS $$
+DECLARE
+  changed_at TIMESTAMPTZ := clock_timestamp();
+BEGIN
+  IF TG_OP IN ('UPDATE', 'DELETE') THEN
+    UPDATE loan_schedule_history SET valid_to = changed_at
+    WHERE schedule_id = OLD.id AND valid_to IS NULL;
+  END IF;
+  IF TG_OP IN ('INSERT', 'UPDATE') THEN
+    INSERT INTO loan_schedule_history (schedule_id, loan_id, operation, row_data, valid_from)
+    VALUES (NEW.id, NEW.loan_id, LEFT(TG_OP, 1), to_jsonb(NEW), changed_at);
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER record_history_loans
+AFTER INSERT OR UPDATE OR DELETE ON loans
+FOR EACH ROW
+EXECUTE FUNCTION record_loans_history();
+
+CREATE TRIGGER record_history_loan_schedule
+AFTER INSERT OR UPDATE OR DELETE ON loan_schedule
+FOR EACH ROW
+EXECUTE FUNCTION record_loan_schedule_history();
+
+-- Existing rows start their history at their last update.
+INSERT INTO loans_history (loan_id, operation, row_data, valid_from)
+SELECT id, 'I', to_jsonb(loans), updated_at FROM loans;
+
+INSERT INTO loan_schedule_history (schedule_id, loan_id, operation, row_data, valid_from)
+SELECT id, loan_id, 'I', to_jsonb(loan_schedule), updated_at FROM loan_schedule;
+
+-- +migrate Down
+
+DROP TRIGGER IF EXISTS record_history_loan_schedule ON loan_schedule;
+DROP TRIGGER IF EXISTS record_history_loans ON loans;
+DROP FUNCTION IF EXISTS record_loan_schedule_history();
+DROP FUNCTION IF EXISTS record_loans_history();
+DROP TABLE IF EXISTS loan_schedule_history;
+DROP TABLE IF EXISTS loans_history;
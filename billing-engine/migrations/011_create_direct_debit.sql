@@ -0,0 +1,63 @@
+-- +migrate Up
+
+-- Customer authorisations to collect installments from a bank account
+CREATE TABLE mandates (
+    id BIGSERIAL PRIMARY KEY,
+    customer_id BIGINT NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    reference VARCHAR(35) NOT NULL,
+    account_holder VARCHAR(140) NOT NULL,
+    account_number VARCHAR(34) NOT NULL,
+    bank_code VARCHAR(11) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'ACTIVE',
+    signed_at DATE NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT uq_mandates_reference UNIQUE (reference),
+    CONSTRAINT chk_mandates_status CHECK (status IN ('ACTIVE', 'CANCELLED'))
+);
+
+-- A customer holds at most one active mandate
+CREATE UNIQUE INDEX IF NOT EXISTS uq_mandates_active_customer ON mandates (customer_id) WHERE status = 'ACTIVE';
+
+CREATE TRIGGER set_timestamp_mandates
+BEFORE UPDATE ON mandates
+FOR EACH ROW
+EXECUTE FUNCTION trigger_set_timestamp();
+
+-- One collection attempt of an installment under a mandate
+CREATE TABLE direct_debit_instructions (
+    id BIGSERIAL PRIMARY KEY,
+    mandate_id BIGINT NOT NULL REFERENCES mandates(id) ON DELETE CASCADE,
+    loan_id BIGINT NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    schedule_id BIGINT NOT NULL REFERENCES loan_schedule(id) ON DELETE CASCADE,
+    week_number INT NOT NULL CHECK (week_number > 0),
+    end_to_end_id VARCHAR(35) NOT NULL,
+    amount DECIMAL(15, 2) NOT NULL CHECK (amount > 0),
+    collection_date DATE NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+    batch_ref VARCHAR(35) NULL,
+    failure_reason TEXT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT uq_direct_debit_instructions_end_to_end_id UNIQUE (end_to_end_id),
+    CONSTRAINT chk_direct_debit_instructions_status
+        CHECK (status IN ('PENDING', 'EXPORTED', 'COLLECTED', 'FAILED', 'UNAPPLIED'))
+);
+
+-- Only failed instructions leave their installment open for another attempt
+CREATE UNIQUE INDEX IF NOT EXISTS uq_direct_debit_instructions_open_schedule
+    ON direct_debit_instructions (schedule_id) WHERE status <> 'FAILED';
+CREATE INDEX IF NOT EXISTS idx_direct_debit_instructions_status
+    ON direct_debit_instructions (status, collection_date);
+
+CREATE TRIGGER set_timestamp_direct_debit_instructions
+BEFORE UPDATE ON direct_debit_instructions
+FOR EACH ROW
+EXECUTE FUNCTION trigger_set_timestamp();
+
+-- +migrate Down
+
+DROP TRIGGER IF EXISTS set_timestamp_direct_debit_instructions ON direct_debit_instructions;
+DROP TABLE IF EXISTS direct_debit_instructions;
+DROP TRIGGER IF EXISTS set_timestamp_mandates ON mandates;
+DROP TABLE IF EXISTS mandates;
@@ -0,0 +1,33 @@
+-- +migrate Up
+
+-- Ledger of the payments applied to installments, for reconciliation by
+-- channel
+CREATE TABLE payments (
+    id BIGSERIAL PRIMARY KEY,
+    loan_id BIGINT NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    schedule_id BIGINT NOT NULL REFERENCES loan_schedule(id) ON DELETE CASCADE,
+    amount DECIMAL(15, 2) NOT NULL CHECK (amount > 0),
+    channel VARCHAR(20) NOT NULL DEFAULT 'UNSPECIFIED',
+    reference VARCHAR(100) NULL,
+    collector_id VARCHAR(64) NULL,
+    paid_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    -- NULL references never collide, so only identified payments are guarded
+    -- against being posted twice
+    CONSTRAINT uq_payments_channel_reference UNIQUE (channel, reference),
+    CONSTRAINT chk_payments_channel
+        CHECK (channel IN ('CASH', 'BANK_TRANSFER', 'GATEWAY', 'DIRECT_DEBIT', 'UNSPECIFIED'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_payments_paid_at ON payments (paid_at);
+CREATE INDEX IF NOT EXISTS idx_payments_loan_id ON payments (loan_id);
+
+-- Installments paid before the ledger existed, without their channel
+INSERT INTO payments (loan_id, schedule_id, amount, channel, paid_at, created_at)
+SELECT loan_id, id, paid_amount, 'UNSPECIFIED', COALESCE(payment_date, updated_at), updated_at
+FROM loan_schedule
+WHERE status = 'PAID' AND paid_amount > 0;
+
+-- +migrate Down
+
+DROP TABLE IF EXISTS payments;
@@ -0,0 +1,14 @@
+-- +migrate Up
+
+-- Days the oldest unpaid installment is overdue, kept up to date by the
+-- nightly delinquency job. Loans start at 0 and are filled in by its next run.
+ALTER TABLE loans ADD COLUMN days_past_due INT NOT NULL DEFAULT 0 CHECK (days_past_due >= 0);
+
+-- Collections queries ask for loans at or above a DPD threshold; current loans
+-- are the bulk of the book and are left out of the index.
+CREATE INDEX IF NOT EXISTS idx_loans_days_past_due ON loans (days_past_due) WHERE days_past_due > 0;
+
+-- +migrate Down
+
+DROP INDEX IF EXISTS idx_loans_days_past_due;
+ALTER TABLE loans DROP COLUMN IF EXISTS days_past_due;
@@ -0,0 +1,76 @@
+-- +migrate Up
+
+-- Rules routing past-due loans to collectors, tried by priority then ID
+CREATE TABLE collection_rules (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    collector VARCHAR(64) NOT NULL,
+    bucket VARCHAR(10) NULL,
+    region VARCHAR(100) NULL,
+    min_outstanding DECIMAL(15, 2) NULL CHECK (min_outstanding >= 0),
+    max_outstanding DECIMAL(15, 2) NULL,
+    priority INT NOT NULL DEFAULT 0,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_collection_rules_bucket CHECK (bucket IN ('1-30', '31-60', '61-90', '90+')),
+    CONSTRAINT chk_collection_rules_outstanding CHECK (max_outstanding >= min_outstanding)
+);
+
+CREATE TRIGGER set_timestamp_collection_rules
+BEFORE UPDATE ON collection_rules
+FOR EACH ROW
+EXECUTE FUNCTION trigger_set_timestamp();
+
+-- A loan in the queue of a collector, from assignment until it is cured or
+-- paid off
+CREATE TABLE collection_assignments (
+    id BIGSERIAL PRIMARY KEY,
+    loan_id BIGINT NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    customer_id BIGINT NULL REFERENCES customers(id) ON DELETE SET NULL,
+    collector VARCHAR(64) NOT NULL,
+    rule_id BIGINT NULL REFERENCES collection_rules(id) ON DELETE SET NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'OPEN',
+    resolution VARCHAR(20) NULL,
+    assigned_at TIMESTAMPTZ NOT NULL,
+    resolved_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_collection_assignments_status CHECK (status IN ('OPEN', 'RESOLVED')),
+    CONSTRAINT chk_collection_assignments_resolution CHECK (resolution IN ('CURED', 'PAID_OFF')),
+    CONSTRAINT chk_collection_assignments_resolved CHECK ((status = 'RESOLVED') = (resolved_at IS NOT NULL))
+);
+
+-- A loan is in at most one queue at a time
+CREATE UNIQUE INDEX IF NOT EXISTS uq_collection_assignments_open_loan ON collection_assignments (loan_id) WHERE status = 'OPEN';
+CREATE INDEX IF NOT EXISTS idx_collection_assignments_open_collector ON collection_assignments (collector) WHERE status = 'OPEN';
+
+CREATE TRIGGER set_timestamp_collection_assignments
+BEFORE UPDATE ON collection_assignments
+FOR EACH ROW
+EXECUTE FUNCTION trigger_set_timestamp();
+
+-- What collectors did about their assigned loans, including reassignments
+CREATE TABLE collection_actions (
+    id BIGSERIAL PRIMARY KEY,
+    assignment_id BIGINT NOT NULL REFERENCES collection_assignments(id) ON DELETE CASCADE,
+    loan_id BIGINT NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    collector VARCHAR(64) NULL,
+    type VARCHAR(20) NOT NULL,
+    note TEXT NULL,
+    promised_amount DECIMAL(15, 2) NULL CHECK (promised_amount > 0),
+    promised_date DATE NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_collection_actions_type
+        CHECK (type IN ('CALL', 'SMS', 'VISIT', 'PROMISE_TO_PAY', 'NOTE', 'REASSIGNED'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_collection_actions_assignment_id ON collection_actions (assignment_id);
+
+-- +migrate Down
+
+DROP TABLE IF EXISTS collection_actions;
+DROP TRIGGER IF EXISTS set_timestamp_collection_assignments ON collection_assignments;
+DROP TABLE IF EXISTS collection_assignments;
+DROP TRIGGER IF EXISTS set_timestamp_collection_rules ON collection_rules;
+DROP TABLE IF EXISTS collection_rules;
@@ -0,0 +1,25 @@
+-- +migrate Up
+
+-- Every event handed to the broker, kept so that consumers that were down can
+-- be backfilled. payload is JSON rather than JSONB so that a replay sends the
+-- original bytes. published_at stays NULL while the broker has not accepted
+-- the event.
+CREATE TABLE event_log (
+    id BIGSERIAL PRIMARY KEY,
+    event_id VARCHAR(64) NOT NULL UNIQUE,
+    event_type VARCHAR(64) NOT NULL,
+    entity_id BIGINT NOT NULL,
+    payload JSON NOT NULL,
+    occurred_at TIMESTAMPTZ NOT NULL,
+    published_at TIMESTAMPTZ NULL,
+    replay_count INT NOT NULL DEFAULT 0,
+    last_replayed_at TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_log_entity_id ON event_log (entity_id);
+CREATE INDEX IF NOT EXISTS idx_event_log_occurred_at ON event_log (occurred_at);
+CREATE INDEX IF NOT EXISTS idx_event_log_unpublished ON event_log (id) WHERE published_at IS NULL;
+
+-- +migrate Down
+
+DROP TABLE IF EXISTS event_log;
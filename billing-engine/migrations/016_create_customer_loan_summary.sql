@@ -0,0 +1,34 @@
+-- +migrate Up
+
+-- One row per customer with the state of their current loan, kept by the
+-- summary projector so that GET /customers/{id}/summary reads a single row.
+-- Rows are recomputed from customers, loans and loan_schedule and can be
+-- rebuilt at any time.
+CREATE TABLE customer_loan_summary (
+    customer_id BIGINT PRIMARY KEY REFERENCES customers(id) ON DELETE CASCADE,
+    customer_public_id UUID NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    address TEXT NOT NULL,
+    active BOOLEAN NOT NULL,
+    is_delinquent BOOLEAN NOT NULL,
+    loan_id BIGINT NULL,
+    loan_public_id UUID NULL,
+    loan_status VARCHAR(20) NULL,
+    principal_amount DECIMAL(15, 2) NOT NULL DEFAULT 0,
+    total_loan_amount DECIMAL(15, 2) NOT NULL DEFAULT 0,
+    total_paid DECIMAL(15, 2) NOT NULL DEFAULT 0,
+    outstanding DECIMAL(15, 2) NOT NULL DEFAULT 0,
+    installments_paid INT NOT NULL DEFAULT 0,
+    installments_total INT NOT NULL DEFAULT 0,
+    next_due_date DATE NULL,
+    next_due_amount DECIMAL(15, 2) NOT NULL DEFAULT 0,
+    days_past_due INT NOT NULL DEFAULT 0,
+    refreshed_at TIMESTAMPTZ NOT NULL
+);
+
+-- Payments name only the loan
+CREATE INDEX IF NOT EXISTS idx_customer_loan_summary_loan_id ON customer_loan_summary (loan_id) WHERE loan_id IS NOT NULL;
+
+-- +migrate Down
+
+DROP TABLE IF EXISTS customer_loan_summary;
@@ -0,0 +1,21 @@
+-- +migrate Up
+
+-- Administrative holds placed on loans. While a hold is open the loan takes
+-- no payments and is left out of direct-debit collection. Released holds stay
+-- as history.
+CREATE TABLE loan_holds (
+    id BIGSERIAL PRIMARY KEY,
+    loan_id BIGINT NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL CHECK (reason <> ''),
+    placed_by VARCHAR(64) NULL,
+    placed_at TIMESTAMPTZ NOT NULL,
+    released_by VARCHAR(64) NULL,
+    released_at TIMESTAMPTZ NULL
+);
+
+-- A loan has at most one open hold
+CREATE UNIQUE INDEX IF NOT EXISTS uq_loan_holds_open_loan ON loan_holds (loan_id) WHERE released_at IS NULL;
+
+-- +migrate Down
+
+DROP TABLE IF EXISTS loan_holds;
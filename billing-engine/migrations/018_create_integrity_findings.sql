@@ -0,0 +1,19 @@
+-- +migrate Up
+
+-- Violations the nightly integrity job found on its last run. A violation
+-- that is still there on the next run keeps its row and first_seen_at; one
+-- that is gone is deleted.
+CREATE TABLE integrity_findings (
+    id BIGSERIAL PRIMARY KEY,
+    check_name VARCHAR(40) NOT NULL,
+    entity_type VARCHAR(20) NOT NULL,
+    entity_id BIGINT NOT NULL,
+    detail TEXT NOT NULL,
+    first_seen_at TIMESTAMPTZ NOT NULL,
+    last_seen_at TIMESTAMPTZ NOT NULL,
+    CONSTRAINT uq_integrity_findings_check_entity UNIQUE (check_name, entity_id)
+);
+
+-- +migrate Down
+
+DROP TABLE IF EXISTS integrity_findings;
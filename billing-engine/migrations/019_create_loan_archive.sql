@@ -0,0 +1,52 @@
+-- +migrate Up
+
+-- Paid-off loans past the retention period are moved out of the live tables.
+-- archived_loans is the catalogue; every other table below mirrors the
+-- columns of its live table, without keys or constraints, so that rows move
+-- back and forth with INSERT ... SELECT *. A migration that adds a column to
+-- one of the live tables adds it to the archive table too.
+CREATE TABLE archived_loans (
+    loan_id BIGINT PRIMARY KEY,
+    public_id UUID NOT NULL,
+    customer_id BIGINT NULL, -- the customer the loan was assigned to
+    completed_at TIMESTAMPTZ NOT NULL,
+    archived_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_archived_loans_archived_at ON archived_loans (archived_at);
+
+CREATE TABLE loans_archive (LIKE loans);
+CREATE TABLE loan_schedule_archive (LIKE loan_schedule);
+CREATE TABLE payments_archive (LIKE payments);
+CREATE TABLE direct_debit_instructions_archive (LIKE direct_debit_instructions);
+CREATE TABLE loan_status_snapshots_archive (LIKE loan_status_snapshots);
+CREATE TABLE collection_assignments_archive (LIKE collection_assignments);
+CREATE TABLE collection_actions_archive (LIKE collection_actions);
+CREATE TABLE loan_holds_archive (LIKE loan_holds);
+CREATE TABLE notes_archive (LIKE notes);
+CREATE TABLE attachments_archive (LIKE attachments);
+
+CREATE INDEX IF NOT EXISTS idx_loans_archive_id ON loans_archive (id);
+CREATE INDEX IF NOT EXISTS idx_loan_schedule_archive_loan_id ON loan_schedule_archive (loan_id);
+CREATE INDEX IF NOT EXISTS idx_payments_archive_loan_id ON payments_archive (loan_id);
+CREATE INDEX IF NOT EXISTS idx_direct_debit_instructions_archive_loan_id ON direct_debit_instructions_archive (loan_id);
+CREATE INDEX IF NOT EXISTS idx_loan_status_snapshots_archive_loan_id ON loan_status_snapshots_archive (loan_id);
+CREATE INDEX IF NOT EXISTS idx_collection_assignments_archive_loan_id ON collection_assignments_archive (loan_id);
+CREATE INDEX IF NOT EXISTS idx_collection_actions_archive_loan_id ON collection_actions_archive (loan_id);
+CREATE INDEX IF NOT EXISTS idx_loan_holds_archive_loan_id ON loan_holds_archive (loan_id);
+CREATE INDEX IF NOT EXISTS idx_notes_archive_loan_id ON notes_archive (loan_id);
+CREATE INDEX IF NOT EXISTS idx_attachments_archive_loan_id ON attachments_archive (loan_id);
+
+-- +migrate Down
+
+DROP TABLE IF EXISTS attachments_archive;
+DROP TABLE IF EXISTS notes_archive;
+DROP TABLE IF EXISTS loan_holds_archive;
+DROP TABLE IF EXISTS collection_actions_archive;
+DROP TABLE IF EXISTS collection_assignments_archive;
+DROP TABLE IF EXISTS loan_status_snapshots_archive;
+DROP TABLE IF EXISTS direct_debit_instructions_archive;
+DROP TABLE IF EXISTS payments_archive;
+DROP TABLE IF EXISTS loan_schedule_archive;
+DROP TABLE IF EXISTS loans_archive;
+DROP TABLE IF EXISTS archived_loans;
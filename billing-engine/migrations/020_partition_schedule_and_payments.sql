@@ -0,0 +1,194 @@
+-- +migrate Up
+
+-- loan_schedule is partitioned by ranges of 100000 loan IDs, so that the
+-- schedule of one loan lives in one partition, and payments by calendar
+-- month (UTC) of paid_at, which the collections reports filter on. The
+-- existing tables are not copied: each becomes the first partition of its new
+-- parent, covering every key up to the end of its current range. The
+-- PartitionMaintenance job creates the partitions that follow. A row no
+-- partition covers goes to the default partition rather than failing.
+
+-- Primary and unique keys of a partitioned table must include the partition
+-- key, so the keys that reference loan_schedule(id) now include loan_id.
+ALTER TABLE payments DROP CONSTRAINT payments_schedule_id_fkey;
+ALTER TABLE direct_debit_instructions DROP CONSTRAINT direct_debit_instructions_schedule_id_fkey;
+
+ALTER TABLE loan_schedule RENAME TO loan_schedule_legacy;
+ALTER TABLE loan_schedule_legacy DROP CONSTRAINT loan_schedule_pkey;
+ALTER TABLE loan_schedule_legacy ADD CONSTRAINT loan_schedule_legacy_pkey PRIMARY KEY (loan_id, id);
+ALTER TABLE loan_schedule_legacy RENAME CONSTRAINT loan_schedule_loan_id_week_number_key TO loan_schedule_legacy_loan_id_week_number_key;
+ALTER INDEX idx_loan_schedule_loan_id_status RENAME TO idx_loan_schedule_legacy_loan_id_status;
+ALTER INDEX idx_loan_schedule_due_date RENAME TO idx_loan_schedule_legacy_due_date;
+ALTER INDEX idx_loan_schedule_loan_id_due_date_status RENAME TO idx_loan_schedule_legacy_loan_id_due_date_status;
+-- The parent's triggers fire for every partition.
+DROP TRIGGER set_timestamp_loan_schedule ON loan_schedule_legacy;
+DROP TRIGGER record_history_loan_schedule ON loan_schedule_legacy;
+
+CREATE TABLE loan_schedule (
+    id BIGINT NOT NULL DEFAULT nextval('loan_schedule_id_seq'),
+    loan_id BIGINT NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    week_number INT NOT NULL CHECK (week_number > 0),
+    due_date DATE NOT NULL,
+    due_amount DECIMAL(15, 2) NOT NULL CHECK (due_amount >= 0),
+    paid_amount DECIMAL(15, 2) DEFAULT 0.00 CHECK (paid_amount >= 0),
+    payment_date TIMESTAMPTZ NULL,
+    status payment_status NOT NULL DEFAULT 'PENDING',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (loan_id, id),
+    UNIQUE (loan_id, week_number)
+) PARTITION BY RANGE (loan_id);
+ALTER SEQUENCE loan_schedule_id_seq OWNED BY loan_schedule.id;
+
+CREATE INDEX idx_loan_schedule_loan_id_status ON loan_schedule(loan_id, status);
+CREATE INDEX idx_loan_schedule_due_date ON loan_schedule(due_date);
+CREATE INDEX idx_loan_schedule_loan_id_due_date_status ON loan_schedule(loan_id, due_date, status);
+
+CREATE TRIGGER set_timestamp_loan_schedule
+BEFORE UPDATE ON loan_schedule
+FOR EACH ROW
+EXECUTE FUNCTION trigger_set_timestamp();
+
+CREATE TRIGGER record_history_loan_schedule
+AFTER INSERT OR UPDATE OR DELETE ON loan_schedule
+FOR EACH ROW
+EXECUTE FUNCTION record_loan_schedule_history();
+
+DO $$
+DECLARE
+  bound BIGINT;
+BEGIN
+  SELECT (GREATEST((SELECT last_value FROM loans_id_seq), COALESCE(MAX(loan_id), 0)) / 100000 + 1) * 100000
+  INTO bound FROM loan_schedule_legacy;
+  EXECUTE format('ALTER TABLE loan_schedule ATTACH PARTITION loan_schedule_legacy FOR VALUES FROM (MINVALUE) TO (%s)', bound);
+  EXECUTE format('CREATE TABLE loan_schedule_p%s PARTITION OF loan_schedule FOR VALUES FROM (%s) TO (%s)',
+    bound / 100000, bound, bound + 100000);
+END;
+$$;
+CREATE TABLE loan_schedule_default PARTITION OF loan_schedule DEFAULT;
+
+ALTER TABLE direct_debit_instructions ADD CONSTRAINT direct_debit_instructions_schedule_id_fkey
+    FOREIGN KEY (loan_id, schedule_id) REFERENCES loan_schedule (loan_id, id) ON DELETE CASCADE;
+
+ALTER TABLE payments RENAME TO payments_legacy;
+ALTER TABLE payments_legacy DROP CONSTRAINT payments_pkey;
+ALTER TABLE payments_legacy ADD CONSTRAINT payments_legacy_pkey PRIMARY KEY (id, paid_at);
+ALTER TABLE payments_legacy DROP CONSTRAINT uq_payments_channel_reference;
+ALTER INDEX idx_payments_paid_at RENAME TO idx_payments_legacy_paid_at;
+ALTER INDEX idx_payments_loan_id RENAME TO idx_payments_legacy_loan_id;
+
+CREATE TABLE payments (
+    id BIGINT NOT NULL DEFAULT nextval('payments_id_seq'),
+    loan_id BIGINT NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    schedule_id BIGINT NOT NULL,
+    amount DECIMAL(15, 2) NOT NULL CHECK (amount > 0),
+    channel VARCHAR(20) NOT NULL DEFAULT 'UNSPECIFIED',
+    reference VARCHAR(100) NULL,
+    collector_id VARCHAR(64) NULL,
+    paid_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (id, paid_at),
+    CONSTRAINT payments_schedule_id_fkey
+        FOREIGN KEY (loan_id, schedule_id) REFERENCES loan_schedule (loan_id, id) ON DELETE CASCADE,
+    CONSTRAINT chk_payments_channel
+        CHECK (channel IN ('CASH', 'BANK_TRANSFER', 'GATEWAY', 'DIRECT_DEBIT', 'UNSPECIFIED'))
+) PARTITION BY RANGE (paid_at);
+ALTER SEQUENCE payments_id_seq OWNED BY payments.id;
+
+CREATE INDEX IF NOT EXISTS idx_payments_paid_at ON payments (paid_at);
+CREATE INDEX IF NOT EXISTS idx_payments_loan_id ON payments (loan_id);
+
+-- A unique constraint on payments would have to include paid_at and so
+-- would let a reference be posted again in another month. The references of
+-- identified payments are kept here instead, under the constraint name the
+-- repositories map to a duplicate payment.
+CREATE TABLE payment_references (
+    channel VARCHAR(20) NOT NULL,
+    reference VARCHAR(100) NOT NULL,
+    payment_id BIGINT NOT NULL,
+    CONSTRAINT uq_payments_channel_reference PRIMARY KEY (channel, reference)
+);
+
+INSERT INTO payment_references (channel, reference, payment_id)
+SELECT channel, reference, id FROM payments_legacy WHERE reference IS NOT NULL;
+
+CREATE OR REPLACE FUNCTION record_payment_reference()
+RETURNS TRIGGER AS $$
+BEGIN
+  IF TG_OP IN ('UPDATE', 'DELETE') AND OLD.reference IS NOT NULL THEN
+    DELETE FROM payment_references WHERE channel = OLD.channel AND reference = OLD.reference;
+  END IF;
+  IF TG_OP IN ('INSERT', 'UPDATE') AND NEW.reference IS NOT NULL THEN
+    INSERT INTO payment_references (channel, reference, payment_id) VALUES (NEW.channel, NEW.reference, NEW.id);
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER record_payment_reference
+AFTER INSERT OR UPDATE OR DELETE ON payments
+FOR EACH ROW
+EXECUTE FUNCTION record_payment_reference();
+
+DO $$
+DECLARE
+  month_start TIMESTAMP;
+BEGIN
+  SELECT date_trunc('month', GREATEST(now(), COALESCE(MAX(paid_at), now())) AT TIME ZONE 'UTC') + INTERVAL '1 month'
+  INTO month_start FROM payments_legacy;
+  EXECUTE format('ALTER TABLE payments ATTACH PARTITION payments_legacy FOR VALUES FROM (MINVALUE) TO (%L)',
+    month_start || '+00');
+  EXECUTE format('CREATE TABLE payments_%s PARTITION OF payments FOR VALUES FROM (%L) TO (%L)',
+    to_char(month_start, '"y"YYYY"m"MM'), month_start || '+00', (month_start + INTERVAL '1 month') || '+00');
+END;
+$$;
+CREATE TABLE payments_default PARTITION OF payments DEFAULT;
+
+-- +migrate Down
+
+-- Rows of the partitions created since are moved back into the legacy
+-- tables, which are then restored as plain tables.
+DROP TRIGGER IF EXISTS record_payment_reference ON payments;
+DROP FUNCTION IF EXISTS record_payment_reference();
+ALTER TABLE direct_debit_instructions DROP CONSTRAINT IF EXISTS direct_debit_instructions_schedule_id_fkey;
+
+ALTER TABLE payments DETACH PARTITION payments_legacy;
+ALTER TABLE payments_legacy DROP CONSTRAINT IF EXISTS payments_schedule_id_fkey;
+INSERT INTO payments_legacy SELECT * FROM payments;
+ALTER SEQUENCE payments_id_seq OWNED BY payments_legacy.id;
+DROP TABLE payments;
+DROP TABLE IF EXISTS payment_references;
+
+ALTER TABLE loan_schedule DETACH PARTITION loan_schedule_legacy;
+DROP TRIGGER IF EXISTS set_timestamp_loan_schedule ON loan_schedule_legacy;
+DROP TRIGGER IF EXISTS record_history_loan_schedule ON loan_schedule_legacy;
+INSERT INTO loan_schedule_legacy SELECT * FROM loan_schedule;
+ALTER SEQUENCE loan_schedule_id_seq OWNED BY loan_schedule_legacy.id;
+DROP TABLE loan_schedule;
+
+ALTER TABLE loan_schedule_legacy RENAME TO loan_schedule;
+ALTER TABLE loan_schedule DROP CONSTRAINT loan_schedule_legacy_pkey;
+ALTER TABLE loan_schedule ADD CONSTRAINT loan_schedule_pkey PRIMARY KEY (id);
+ALTER TABLE loan_schedule RENAME CONSTRAINT loan_schedule_legacy_loan_id_week_number_key TO loan_schedule_loan_id_week_number_key;
+ALTER INDEX idx_loan_schedule_legacy_loan_id_status RENAME TO idx_loan_schedule_loan_id_status;
+ALTER INDEX idx_loan_schedule_legacy_due_date RENAME TO idx_loan_schedule_due_date;
+ALTER INDEX idx_loan_schedule_legacy_loan_id_due_date_status RENAME TO idx_loan_schedule_loan_id_due_date_status;
+CREATE TRIGGER set_timestamp_loan_schedule
+BEFORE UPDATE ON loan_schedule
+FOR EACH ROW
+EXECUTE FUNCTION trigger_set_timestamp();
+CREATE TRIGGER record_history_loan_schedule
+AFTER INSERT OR UPDATE OR DELETE ON loan_schedule
+FOR EACH ROW
+EXECUTE FUNCTION record_loan_schedule_history();
+
+ALTER TABLE payments_legacy RENAME TO payments;
+ALTER TABLE payments DROP CONSTRAINT payments_legacy_pkey;
+ALTER TABLE payments ADD CONSTRAINT payments_pkey PRIMARY KEY (id);
+ALTER TABLE payments ADD CONSTRAINT uq_payments_channel_reference UNIQUE (channel, reference);
+ALTER INDEX idx_payments_legacy_paid_at RENAME TO idx_payments_paid_at;
+ALTER INDEX idx_payments_legacy_loan_id RENAME TO idx_payments_loan_id;
+ALTER TABLE payments ADD CONSTRAINT payments_schedule_id_fkey
+    FOREIGN KEY (schedule_id) REFERENCES loan_schedule(id) ON DELETE CASCADE;
+ALTER TABLE direct_debit_instructions ADD CONSTRAINT direct_debit_instructions_schedule_id_fkey
+    FOREIGN KEY (schedule_id) REFERENCES loan_schedule(id) ON DELETE CASCADE;
@@ -0,0 +1,36 @@
+-- +migrate Up
+
+-- Async jobs: bulk uploads handed off by the API, event replays and the runs
+-- of the batch jobs. Every instance polls this table for work, claims a job
+-- with FOR UPDATE SKIP LOCKED and renews heartbeat_at while it runs it; a
+-- running job whose heartbeat is older than the lease is claimed again.
+-- Finished jobs are deleted after the retention.
+CREATE TABLE jobs (
+    id UUID PRIMARY KEY,
+    kind VARCHAR(64) NOT NULL,
+    status VARCHAR(16) NOT NULL CHECK (status IN ('QUEUED', 'RUNNING', 'SUCCEEDED', 'FAILED')),
+    -- Scheduled runs carry their job name and time, so that the instances
+    -- whose cron fires for the same run queue it once.
+    dedupe_key VARCHAR(128) NULL,
+    payload JSONB NULL,
+    result JSONB NULL,
+    error TEXT NULL,
+    total INTEGER NOT NULL DEFAULT 0,
+    processed INTEGER NOT NULL DEFAULT 0,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    worker VARCHAR(128) NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    started_at TIMESTAMPTZ NULL,
+    heartbeat_at TIMESTAMPTZ NULL,
+    finished_at TIMESTAMPTZ NULL,
+    CONSTRAINT uq_jobs_dedupe_key UNIQUE (dedupe_key)
+);
+
+-- Serves the claim: only unfinished jobs are indexed
+CREATE INDEX IF NOT EXISTS idx_jobs_unfinished ON jobs (created_at) WHERE status IN ('QUEUED', 'RUNNING');
+CREATE INDEX IF NOT EXISTS idx_jobs_finished_at ON jobs (finished_at) WHERE finished_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_jobs_kind_created_at ON jobs (kind, created_at);
+
+-- +migrate Down
+
+DROP TABLE IF EXISTS jobs;
@@ -0,0 +1,17 @@
+-- +migrate Up
+
+-- Communication preferences a customer has set. Customers without a row have
+-- no preferred channel or language and have opted out of nothing.
+-- notify-service keeps its own copy from customer.preferences.changed events.
+CREATE TABLE customer_preferences (
+    customer_id BIGINT PRIMARY KEY REFERENCES customers(id) ON DELETE CASCADE,
+    preferred_channel VARCHAR(16) NOT NULL DEFAULT '' CHECK (preferred_channel IN ('', 'sms', 'email')),
+    language VARCHAR(35) NOT NULL DEFAULT '',
+    marketing_opt_out BOOLEAN NOT NULL DEFAULT FALSE,
+    transactional_opt_out BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+-- +migrate Down
+
+DROP TABLE IF EXISTS customer_preferences;
@@ -0,0 +1,24 @@
+-- +migrate Up
+
+-- Rate changes of variable-rate loans. From effective_from on, installments
+-- are charged rate instead of previous_rate; loans.interest_rate holds the
+-- latest rate and the schedule the recalculated amounts. A loan's changes
+-- take effect in the order they were made.
+CREATE TABLE rate_history (
+    id BIGSERIAL PRIMARY KEY,
+    loan_id BIGINT NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    previous_rate DECIMAL(5, 4) NOT NULL CHECK (previous_rate >= 0),
+    rate DECIMAL(5, 4) NOT NULL CHECK (rate >= 0),
+    effective_from DATE NOT NULL,
+    changed_by VARCHAR(64) NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    CONSTRAINT uq_rate_history_loan_effective_from UNIQUE (loan_id, effective_from)
+);
+
+CREATE TABLE rate_history_archive (LIKE rate_history);
+CREATE INDEX IF NOT EXISTS idx_rate_history_archive_loan_id ON rate_history_archive (loan_id);
+
+-- +migrate Down
+
+DROP TABLE IF EXISTS rate_history_archive;
+DROP TABLE IF EXISTS rate_history;
@@ -0,0 +1,29 @@
+-- +migrate Up
+
+-- Payment holidays and promotional zero-interest windows. A payment holiday
+-- moves the installments due from starts_on on back by weeks weeks; a
+-- zero-interest window waives the interest of those due in the weeks from
+-- starts_on. The schedule and loans.total_loan_amount hold the result.
+-- Removed adjustments are kept with who removed them.
+CREATE TABLE schedule_adjustments (
+    id BIGSERIAL PRIMARY KEY,
+    loan_id BIGINT NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('PAYMENT_HOLIDAY', 'ZERO_INTEREST')),
+    starts_on DATE NOT NULL,
+    weeks INT NOT NULL CHECK (weeks > 0),
+    reason TEXT NOT NULL,
+    applied_by VARCHAR(64) NULL,
+    applied_at TIMESTAMPTZ NOT NULL,
+    removed_by VARCHAR(64) NULL,
+    removed_at TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_schedule_adjustments_loan_id ON schedule_adjustments (loan_id);
+
+CREATE TABLE schedule_adjustments_archive (LIKE schedule_adjustments);
+CREATE INDEX IF NOT EXISTS idx_schedule_adjustments_archive_loan_id ON schedule_adjustments_archive (loan_id);
+
+-- +migrate Down
+
+DROP TABLE IF EXISTS schedule_adjustments_archive;
+DROP TABLE IF EXISTS schedule_adjustments;
@@ -0,0 +1,40 @@
+-- +migrate Up
+
+-- Lump sums paid against a loan's principal on top of its installments. The
+-- installments after after_week were reamortized to the terms on the row:
+-- each repays weekly_principal of principal, the last the rest, and either
+-- the term or the installment shrank as the borrower chose. weekly_principal
+-- is kept unrounded since the schedule is regenerated from it. The previous
+-- terms are kept for the audit trail.
+CREATE TABLE prepayments (
+    id BIGSERIAL PRIMARY KEY,
+    loan_id BIGINT NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    option VARCHAR(20) NOT NULL CHECK (option IN ('REDUCE_TERM', 'REDUCE_INSTALLMENT')),
+    amount DECIMAL(15, 2) NOT NULL CHECK (amount > 0),
+    after_week INT NOT NULL CHECK (after_week >= 0),
+    principal DECIMAL(15, 2) NOT NULL CHECK (principal > 0),
+    weekly_principal DOUBLE PRECISION NOT NULL CHECK (weekly_principal > 0),
+    previous_term_weeks INT NOT NULL,
+    previous_weekly_payment_amount DECIMAL(15, 2) NOT NULL,
+    previous_total_loan_amount DECIMAL(15, 2) NOT NULL,
+    term_weeks INT NOT NULL CHECK (term_weeks > after_week),
+    weekly_payment_amount DECIMAL(15, 2) NOT NULL,
+    total_loan_amount DECIMAL(15, 2) NOT NULL,
+    channel VARCHAR(20) NOT NULL DEFAULT 'UNSPECIFIED',
+    reference VARCHAR(100) NULL,
+    recorded_by VARCHAR(64) NULL,
+    paid_at TIMESTAMPTZ NOT NULL,
+    CONSTRAINT uq_prepayments_channel_reference UNIQUE (channel, reference),
+    CONSTRAINT chk_prepayments_channel
+        CHECK (channel IN ('CASH', 'BANK_TRANSFER', 'GATEWAY', 'DIRECT_DEBIT', 'UNSPECIFIED'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_prepayments_loan_id ON prepayments (loan_id);
+
+CREATE TABLE prepayments_archive (LIKE prepayments);
+CREATE INDEX IF NOT EXISTS idx_prepayments_archive_loan_id ON prepayments_archive (loan_id);
+
+-- +migrate Down
+
+DROP TABLE IF EXISTS prepayments_archive;
+DROP TABLE IF EXISTS prepayments;
@@ -0,0 +1,48 @@
+-- +migrate Up
+
+-- Charges posted to a loan outside its schedule, typed by the fee catalog.
+-- A fee is owed on top of the installments until a waiver of it is
+-- approved.
+CREATE TABLE fees (
+    id BIGSERIAL PRIMARY KEY,
+    loan_id BIGINT NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    fee_type VARCHAR(20) NOT NULL CHECK (fee_type IN ('PROCESSING', 'BOUNCE', 'LEGAL')),
+    amount DECIMAL(15, 2) NOT NULL CHECK (amount > 0),
+    reason TEXT NOT NULL CHECK (reason <> ''),
+    posted_by VARCHAR(64) NULL,
+    posted_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_fees_loan_id ON fees (loan_id);
+
+-- Requests to write a fee off and the decision on each. loan_id repeats the
+-- fee's so the rows archive with the loan. Rejected requests stay as
+-- history.
+CREATE TABLE fee_waivers (
+    id BIGSERIAL PRIMARY KEY,
+    fee_id BIGINT NOT NULL REFERENCES fees(id) ON DELETE CASCADE,
+    loan_id BIGINT NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL CHECK (reason <> ''),
+    status VARCHAR(20) NOT NULL CHECK (status IN ('PENDING', 'APPROVED', 'REJECTED')),
+    requested_by VARCHAR(64) NULL,
+    requested_at TIMESTAMPTZ NOT NULL,
+    decided_by VARCHAR(64) NULL,
+    decided_at TIMESTAMPTZ NULL,
+    CONSTRAINT chk_fee_waivers_decided CHECK ((status = 'PENDING') = (decided_at IS NULL))
+);
+
+-- A fee has at most one waiver that is pending or approved
+CREATE UNIQUE INDEX IF NOT EXISTS uq_fee_waivers_open_fee ON fee_waivers (fee_id) WHERE status <> 'REJECTED';
+CREATE INDEX IF NOT EXISTS idx_fee_waivers_loan_id ON fee_waivers (loan_id);
+
+CREATE TABLE fees_archive (LIKE fees);
+CREATE INDEX IF NOT EXISTS idx_fees_archive_loan_id ON fees_archive (loan_id);
+CREATE TABLE fee_waivers_archive (LIKE fee_waivers);
+CREATE INDEX IF NOT EXISTS idx_fee_waivers_archive_loan_id ON fee_waivers_archive (loan_id);
+
+-- +migrate Down
+
+DROP TABLE IF EXISTS fee_waivers_archive;
+DROP TABLE IF EXISTS fees_archive;
+DROP TABLE IF EXISTS fee_waivers;
+DROP TABLE IF EXISTS fees;
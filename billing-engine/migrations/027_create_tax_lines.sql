@@ -0,0 +1,26 @@
+-- +migrate Up
+
+-- The tax charged on a fee, at the jurisdiction and rate in force when it
+-- was posted. A fee has at most one. loan_id repeats the fee's so the rows
+-- archive with the loan.
+CREATE TABLE tax_lines (
+    id BIGSERIAL PRIMARY KEY,
+    fee_id BIGINT NOT NULL UNIQUE REFERENCES fees(id) ON DELETE CASCADE,
+    loan_id BIGINT NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    jurisdiction VARCHAR(32) NOT NULL CHECK (jurisdiction <> ''),
+    rate DECIMAL(6, 4) NOT NULL CHECK (rate > 0 AND rate < 1),
+    taxable_amount DECIMAL(15, 2) NOT NULL CHECK (taxable_amount > 0),
+    amount DECIMAL(15, 2) NOT NULL CHECK (amount > 0),
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_tax_lines_loan_id ON tax_lines (loan_id);
+CREATE INDEX IF NOT EXISTS idx_tax_lines_created_at ON tax_lines (created_at);
+
+CREATE TABLE tax_lines_archive (LIKE tax_lines);
+CREATE INDEX IF NOT EXISTS idx_tax_lines_archive_loan_id ON tax_lines_archive (loan_id);
+
+-- +migrate Down
+
+DROP TABLE IF EXISTS tax_lines_archive;
+DROP TABLE IF EXISTS tax_lines;
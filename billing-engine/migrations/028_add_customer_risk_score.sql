@@ -0,0 +1,16 @@
+-- +migrate Up
+
+-- The customer's latest credit bureau assessment. Score, grade and the time
+-- it was scored are set together, and are NULL until the first assessment.
+ALTER TABLE customers ADD COLUMN risk_score INTEGER NULL CHECK (risk_score BETWEEN 0 AND 1000);
+ALTER TABLE customers ADD COLUMN risk_grade VARCHAR(8) NULL;
+ALTER TABLE customers ADD COLUMN risk_scored_at TIMESTAMPTZ NULL;
+ALTER TABLE customers ADD CONSTRAINT chk_customers_risk_complete
+    CHECK ((risk_score IS NULL) = (risk_grade IS NULL) AND (risk_grade IS NULL) = (risk_scored_at IS NULL));
+
+-- +migrate Down
+
+ALTER TABLE customers DROP CONSTRAINT IF EXISTS chk_customers_risk_complete;
+ALTER TABLE customers DROP COLUMN IF EXISTS risk_scored_at;
+ALTER TABLE customers DROP COLUMN IF EXISTS risk_grade;
+ALTER TABLE customers DROP COLUMN IF EXISTS risk_score;
@@ -0,0 +1,30 @@
+-- +migrate Up
+
+-- The reminder ladder: what happens once a loan is days_past_due days past
+-- due. SMS and EMAIL remind the customer, CALL and LETTER are tasks for the
+-- collections team.
+CREATE TABLE escalation_steps (
+    days_past_due INT PRIMARY KEY CHECK (days_past_due BETWEEN 1 AND 3650),
+    action VARCHAR(10) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_escalation_steps_action CHECK (action IN ('SMS', 'EMAIL', 'CALL', 'LETTER'))
+);
+
+INSERT INTO escalation_steps (days_past_due, action) VALUES
+    (1, 'SMS'),
+    (7, 'EMAIL'),
+    (14, 'CALL'),
+    (30, 'LETTER');
+
+-- The step each past-due loan last reached, so a run raises every step once.
+-- The row is removed when the loan is current again.
+CREATE TABLE loan_escalations (
+    loan_id BIGINT PRIMARY KEY REFERENCES loans(id) ON DELETE CASCADE,
+    days_past_due INT NOT NULL,
+    escalated_at TIMESTAMPTZ NOT NULL
+);
+
+-- +migrate Down
+
+DROP TABLE IF EXISTS loan_escalations;
+DROP TABLE IF EXISTS escalation_steps;
@@ -0,0 +1,24 @@
+-- +migrate Up
+
+-- Loan search looks payments up by reference alone, without the channel the
+-- reference was posted through, which the payment_references key starts
+-- with. Unidentified payments have no reference and are left out of the
+-- index.
+CREATE INDEX IF NOT EXISTS idx_payments_reference ON payments (reference) WHERE reference IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_prepayments_reference ON prepayments (reference) WHERE reference IS NOT NULL;
+
+-- Searches by amount are bounded by a date window; payments are pruned by
+-- partition and read through idx_payments_paid_at, prepayments need their own.
+CREATE INDEX IF NOT EXISTS idx_prepayments_paid_at ON prepayments (paid_at);
+
+-- Customer names are matched on any part, case-insensitively, which a
+-- b-tree on name cannot serve.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE INDEX IF NOT EXISTS idx_customers_name_trgm ON customers USING gin (lower(name) gin_trgm_ops);
+
+-- +migrate Down
+
+DROP INDEX IF EXISTS idx_customers_name_trgm;
+DROP INDEX IF EXISTS idx_prepayments_paid_at;
+DROP INDEX IF EXISTS idx_prepayments_reference;
+DROP INDEX IF EXISTS idx_payments_reference;
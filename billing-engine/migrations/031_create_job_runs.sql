@@ -0,0 +1,29 @@
+-- +migrate Up
+
+-- Runs of the scheduled batch jobs. The async job that carries a run is
+-- deleted after the job retention; its run is kept, with the counts the job
+-- reported and the first item errors, so that past outcomes can be read
+-- without the logs. A run whose job is claimed again after its worker
+-- stopped is closed as failed when the new run starts.
+CREATE TABLE job_runs (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(64) NOT NULL,
+    -- No foreign key: the job row goes away long before its run.
+    job_id UUID NULL,
+    status VARCHAR(16) NOT NULL CHECK (status IN ('RUNNING', 'SUCCEEDED', 'FAILED')),
+    total INTEGER NOT NULL DEFAULT 0,
+    processed INTEGER NOT NULL DEFAULT 0,
+    failed INTEGER NOT NULL DEFAULT 0,
+    error_samples JSONB NOT NULL DEFAULT '[]',
+    error TEXT NULL,
+    started_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+    finished_at TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_job_runs_name_started_at ON job_runs (name, started_at DESC);
+CREATE INDEX IF NOT EXISTS idx_job_runs_running ON job_runs (job_id) WHERE status = 'RUNNING';
+
+-- +migrate Down
+
+DROP TABLE IF EXISTS job_runs;
@@ -0,0 +1,15 @@
+-- +migrate Up
+
+-- Locks the runs of a batch job take so that the next run, on any instance,
+-- can tell the previous one is still going. A lock is held until expires_at
+-- and extended by its owner, the async job carrying the run, while it runs;
+-- one whose instance stopped lapses and can be taken by the next owner.
+CREATE TABLE job_locks (
+    name VARCHAR(64) PRIMARY KEY,
+    owner VARCHAR(64) NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+-- +migrate Down
+
+DROP TABLE IF EXISTS job_locks;
@@ -0,0 +1,77 @@
+-- +migrate Up
+
+-- The loan's next installment and how many are paid and left, so that reads
+-- of a loan need not load its schedule. A trigger recomputes them from
+-- loan_schedule with every write to it, in the same transaction, including
+-- writes made outside the application. The next installment is the oldest
+-- unpaid one and next_due_amount what is left on it; both are NULL and 0
+-- once the loan is paid off.
+ALTER TABLE loans ADD COLUMN next_due_date DATE NULL;
+ALTER TABLE loans ADD COLUMN next_due_amount DECIMAL(15, 2) NOT NULL DEFAULT 0;
+ALTER TABLE loans ADD COLUMN installments_paid INT NOT NULL DEFAULT 0;
+ALTER TABLE loans ADD COLUMN installments_remaining INT NOT NULL DEFAULT 0;
+
+-- Archiving copies whole rows, so the archive keeps the live table's columns
+-- in the same order.
+ALTER TABLE loans_archive ADD COLUMN next_due_date DATE NULL;
+ALTER TABLE loans_archive ADD COLUMN next_due_amount DECIMAL(15, 2) NOT NULL DEFAULT 0;
+ALTER TABLE loans_archive ADD COLUMN installments_paid INT NOT NULL DEFAULT 0;
+ALTER TABLE loans_archive ADD COLUMN installments_remaining INT NOT NULL DEFAULT 0;
+
+CREATE OR REPLACE FUNCTION refresh_loan_installments(target BIGINT)
+RETURNS VOID AS $$
+BEGIN
+  UPDATE loans l
+  SET next_due_date = n.due_date, next_due_amount = COALESCE(n.due_amount - n.paid_amount, 0),
+      installments_paid = s.paid, installments_remaining = s.remaining
+  FROM (SELECT COUNT(*) FILTER (WHERE status = 'PAID') AS paid,
+               COUNT(*) FILTER (WHERE status != 'PAID') AS remaining
+        FROM loan_schedule WHERE loan_id = target) s
+  LEFT JOIN LATERAL (
+      SELECT due_date, due_amount, paid_amount FROM loan_schedule
+      WHERE loan_id = target AND status != 'PAID'
+      ORDER BY due_date, week_number
+      LIMIT 1) n ON TRUE
+  WHERE l.id = target
+    AND (l.next_due_date, l.next_due_amount, l.installments_paid, l.installments_remaining)
+        IS DISTINCT FROM (n.due_date, COALESCE(n.due_amount - n.paid_amount, 0), s.paid::INT, s.remaining::INT);
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION refresh_loan_installments_on_schedule()
+RETURNS TRIGGER AS $$
+BEGIN
+  IF TG_OP = 'DELETE' THEN
+    PERFORM refresh_loan_installments(OLD.loan_id);
+  ELSE
+    PERFORM refresh_loan_installments(NEW.loan_id);
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- The parent's trigger fires for every partition.
+CREATE TRIGGER refresh_loan_installments
+AFTER INSERT OR UPDATE OR DELETE ON loan_schedule
+FOR EACH ROW
+EXECUTE FUNCTION refresh_loan_installments_on_schedule();
+
+-- Existing loans are filled in without moving their updated_at, which
+-- clients compare with If-Modified-Since.
+ALTER TABLE loans DISABLE TRIGGER set_timestamp_loans;
+SELECT refresh_loan_installments(id) FROM loans;
+ALTER TABLE loans ENABLE TRIGGER set_timestamp_loans;
+
+-- +migrate Down
+
+DROP TRIGGER IF EXISTS refresh_loan_installments ON loan_schedule;
+DROP FUNCTION IF EXISTS refresh_loan_installments_on_schedule();
+DROP FUNCTION IF EXISTS refresh_loan_installments(BIGINT);
+ALTER TABLE loans_archive DROP COLUMN IF EXISTS installments_remaining;
+ALTER TABLE loans_archive DROP COLUMN IF EXISTS installments_paid;
+ALTER TABLE loans_archive DROP COLUMN IF EXISTS next_due_amount;
+ALTER TABLE loans_archive DROP COLUMN IF EXISTS next_due_date;
+ALTER TABLE loans DROP COLUMN IF EXISTS installments_remaining;
+ALTER TABLE loans DROP COLUMN IF EXISTS installments_paid;
+ALTER TABLE loans DROP COLUMN IF EXISTS next_due_amount;
+ALTER TABLE loans DROP COLUMN IF EXISTS next_due_date;
@@ -0,0 +1,17 @@
+-- +migrate Up
+
+-- A duplicate customer merged into another keeps its row, deactivated, and
+-- points at the customer that took over its loan and records. Both columns
+-- are set together and stay NULL for customers that were never merged.
+ALTER TABLE customers ADD COLUMN merged_into_id BIGINT NULL REFERENCES customers(id);
+ALTER TABLE customers ADD COLUMN merged_at TIMESTAMPTZ NULL;
+ALTER TABLE customers ADD CONSTRAINT chk_customers_merge_complete
+    CHECK ((merged_into_id IS NULL) = (merged_at IS NULL) AND merged_into_id IS DISTINCT FROM id);
+CREATE INDEX IF NOT EXISTS idx_customers_merged_into_id ON customers (merged_into_id) WHERE merged_into_id IS NOT NULL;
+
+-- +migrate Down
+
+DROP INDEX IF EXISTS idx_customers_merged_into_id;
+ALTER TABLE customers DROP CONSTRAINT IF EXISTS chk_customers_merge_complete;
+ALTER TABLE customers DROP COLUMN IF EXISTS merged_at;
+ALTER TABLE customers DROP COLUMN IF EXISTS merged_into_id;
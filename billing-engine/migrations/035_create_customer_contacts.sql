@@ -0,0 +1,41 @@
+-- +migrate Up
+
+-- Phone numbers and email addresses a customer can be reached at. Only
+-- verified contacts are sent to, and each type has at most one primary.
+CREATE TABLE IF NOT EXISTS customer_contacts (
+    id BIGSERIAL PRIMARY KEY,
+    customer_id BIGINT NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    type VARCHAR(10) NOT NULL,
+    value VARCHAR(254) NOT NULL,
+    verified BOOLEAN NOT NULL DEFAULT FALSE,
+    is_primary BOOLEAN NOT NULL DEFAULT FALSE,
+    verified_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT uq_customer_contacts_value UNIQUE (customer_id, type, value),
+    CONSTRAINT chk_customer_contacts_type CHECK (type IN ('phone', 'email')),
+    CONSTRAINT chk_customer_contacts_primary_verified CHECK (NOT is_primary OR verified)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_customer_contacts_primary ON customer_contacts (customer_id, type) WHERE is_primary;
+
+CREATE TRIGGER set_timestamp_customer_contacts
+BEFORE UPDATE ON customer_contacts
+FOR EACH ROW
+EXECUTE FUNCTION trigger_set_timestamp();
+
+-- The code pending for a contact, kept as a hash. value is what the code was
+-- sent to.
+CREATE TABLE IF NOT EXISTS customer_contact_verifications (
+    contact_id BIGINT PRIMARY KEY REFERENCES customer_contacts(id) ON DELETE CASCADE,
+    value VARCHAR(254) NOT NULL,
+    code_hash CHAR(64) NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    sent_at TIMESTAMPTZ NOT NULL
+);
+
+-- +migrate Down
+
+DROP TABLE IF EXISTS customer_contact_verifications;
+DROP TABLE IF EXISTS customer_contacts;
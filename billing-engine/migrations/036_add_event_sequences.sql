@@ -0,0 +1,17 @@
+-- +migrate Up
+
+-- The last number handed to an event about each customer. Events are
+-- numbered from 1 per customer so that consumers can tell when one is
+-- missing or arrived out of order. event_log.sequence stays NULL for events
+-- recorded before they were numbered.
+CREATE TABLE IF NOT EXISTS event_sequences (
+    entity_id BIGINT PRIMARY KEY,
+    last_sequence BIGINT NOT NULL
+);
+
+ALTER TABLE event_log ADD COLUMN IF NOT EXISTS sequence BIGINT NULL;
+
+-- +migrate Down
+
+ALTER TABLE event_log DROP COLUMN IF EXISTS sequence;
+DROP TABLE IF EXISTS event_sequences;
@@ -0,0 +1,13 @@
+-- +migrate Up
+
+-- The DPD bucket a delinquent customer was last sent a delinquency notice
+-- for, and when. The nightly delinquency run sends a notice only when the
+-- bucket differs, and clears it once the customer is no longer delinquent,
+-- so a customer hears once per delinquency and once per bucket they move to.
+ALTER TABLE customers ADD COLUMN notified_for_dpd_bucket VARCHAR(16) NULL;
+ALTER TABLE customers ADD COLUMN last_notified_delinquency_at TIMESTAMPTZ NULL;
+
+-- +migrate Down
+
+ALTER TABLE customers DROP COLUMN IF EXISTS last_notified_delinquency_at;
+ALTER TABLE customers DROP COLUMN IF EXISTS notified_for_dpd_bucket;
@@ -0,0 +1,24 @@
+-- +migrate Up
+
+-- Documents the engine generates for a loan, such as the payoff certificate
+-- issued once the loan is paid off. The content lives in object storage
+-- under storage_key; a loan has at most one document of each type.
+CREATE TABLE IF NOT EXISTS loan_documents (
+    id BIGSERIAL PRIMARY KEY,
+    loan_id BIGINT NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    type VARCHAR(32) NOT NULL CHECK (type IN ('PAYOFF_CERTIFICATE')),
+    file_name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    size_bytes BIGINT NOT NULL CHECK (size_bytes > 0),
+    storage_key TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    CONSTRAINT uq_loan_documents_type UNIQUE (loan_id, type)
+);
+
+CREATE TABLE loan_documents_archive (LIKE loan_documents);
+CREATE INDEX IF NOT EXISTS idx_loan_documents_archive_loan_id ON loan_documents_archive (loan_id);
+
+-- +migrate Down
+
+DROP TABLE IF EXISTS loan_documents_archive;
+DROP TABLE IF EXISTS loan_documents;
@@ -0,0 +1,62 @@
+-- +migrate Up
+
+-- Documents now belong to a loan or a customer and are uploaded by staff as
+-- well as generated: loan contracts and customer ID copies next to payoff
+-- certificates. An upload is PENDING from the moment its upload link is
+-- handed out until the client confirms the file arrived in object storage.
+ALTER TABLE loan_documents RENAME TO documents;
+ALTER TABLE loan_documents_archive RENAME TO documents_archive;
+ALTER INDEX idx_loan_documents_archive_loan_id RENAME TO idx_documents_archive_loan_id;
+
+ALTER TABLE documents ALTER COLUMN loan_id DROP NOT NULL;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS customer_id BIGINT NULL REFERENCES customers(id) ON DELETE CASCADE;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'AVAILABLE';
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS uploaded_by VARCHAR(255) NULL;
+ALTER TABLE documents ADD CONSTRAINT chk_documents_subject CHECK ((loan_id IS NULL) <> (customer_id IS NULL));
+ALTER TABLE documents ADD CONSTRAINT chk_documents_status CHECK (status IN ('PENDING', 'AVAILABLE'));
+ALTER TABLE documents DROP CONSTRAINT IF EXISTS loan_documents_type_check;
+ALTER TABLE documents ADD CONSTRAINT chk_documents_type CHECK (type IN ('CONTRACT', 'ID_COPY', 'PAYOFF_CERTIFICATE'));
+ALTER TABLE documents ADD CONSTRAINT uq_documents_storage_key UNIQUE (storage_key);
+
+-- A loan still gets one payoff certificate, but any number of contracts.
+ALTER TABLE documents DROP CONSTRAINT IF EXISTS uq_loan_documents_type;
+CREATE UNIQUE INDEX IF NOT EXISTS uq_documents_payoff_certificate ON documents (loan_id) WHERE type = 'PAYOFF_CERTIFICATE';
+CREATE INDEX IF NOT EXISTS idx_documents_loan_id ON documents (loan_id) WHERE loan_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_documents_customer_id ON documents (customer_id) WHERE customer_id IS NOT NULL;
+-- The retention run looks documents up by type and age.
+CREATE INDEX IF NOT EXISTS idx_documents_type_created_at ON documents (type, created_at);
+
+-- Archived loans keep their documents; the archive copies rows column for
+-- column, so it gains the same columns in the same order.
+ALTER TABLE documents_archive ALTER COLUMN loan_id DROP NOT NULL;
+ALTER TABLE documents_archive ADD COLUMN IF NOT EXISTS customer_id BIGINT NULL;
+ALTER TABLE documents_archive ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'AVAILABLE';
+ALTER TABLE documents_archive ADD COLUMN IF NOT EXISTS uploaded_by VARCHAR(255) NULL;
+
+-- +migrate Down
+
+DELETE FROM documents_archive WHERE loan_id IS NULL;
+ALTER TABLE documents_archive DROP COLUMN IF EXISTS uploaded_by;
+ALTER TABLE documents_archive DROP COLUMN IF EXISTS status;
+ALTER TABLE documents_archive DROP COLUMN IF EXISTS customer_id;
+ALTER TABLE documents_archive ALTER COLUMN loan_id SET NOT NULL;
+
+DELETE FROM documents WHERE loan_id IS NULL OR type <> 'PAYOFF_CERTIFICATE';
+DROP INDEX IF EXISTS idx_documents_type_created_at;
+DROP INDEX IF EXISTS idx_documents_customer_id;
+DROP INDEX IF EXISTS idx_documents_loan_id;
+DROP INDEX IF EXISTS uq_documents_payoff_certificate;
+ALTER TABLE documents ADD CONSTRAINT uq_loan_documents_type UNIQUE (loan_id, type);
+ALTER TABLE documents DROP CONSTRAINT IF EXISTS uq_documents_storage_key;
+ALTER TABLE documents DROP CONSTRAINT IF EXISTS chk_documents_type;
+ALTER TABLE documents ADD CONSTRAINT loan_documents_type_check CHECK (type IN ('PAYOFF_CERTIFICATE'));
+ALTER TABLE documents DROP CONSTRAINT IF EXISTS chk_documents_status;
+ALTER TABLE documents DROP CONSTRAINT IF EXISTS chk_documents_subject;
+ALTER TABLE documents DROP COLUMN IF EXISTS uploaded_by;
+ALTER TABLE documents DROP COLUMN IF EXISTS status;
+ALTER TABLE documents DROP COLUMN IF EXISTS customer_id;
+ALTER TABLE documents ALTER COLUMN loan_id SET NOT NULL;
+
+ALTER INDEX idx_documents_archive_loan_id RENAME TO idx_loan_documents_archive_loan_id;
+ALTER TABLE documents_archive RENAME TO loan_documents_archive;
+ALTER TABLE documents RENAME TO loan_documents;
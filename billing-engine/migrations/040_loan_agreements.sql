@@ -0,0 +1,35 @@
+-- +migrate Up
+
+-- A loan created in workflow mode is disbursed only after the customer signs
+-- its agreement through the e-signature provider. Each row is one envelope
+-- sent for a loan; a loan has at most one that is still sent or signed, and
+-- a declined or voided one is followed by a new row. The loan awaits
+-- signature under hold_id, released when disbursed_at is set.
+CREATE TABLE IF NOT EXISTS loan_agreements (
+    id BIGSERIAL PRIMARY KEY,
+    loan_id BIGINT NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    document_id BIGINT NULL REFERENCES documents(id) ON DELETE SET NULL,
+    hold_id BIGINT NULL REFERENCES loan_holds(id) ON DELETE SET NULL,
+    provider VARCHAR(32) NOT NULL,
+    envelope_id VARCHAR(255) NOT NULL,
+    status VARCHAR(16) NOT NULL CHECK (status IN ('SENT', 'COMPLETED', 'DECLINED', 'VOIDED')),
+    sent_by VARCHAR(255) NULL,
+    sent_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+    signed_at TIMESTAMPTZ NULL,
+    disbursed_by VARCHAR(255) NULL,
+    disbursed_at TIMESTAMPTZ NULL,
+    CONSTRAINT uq_loan_agreements_envelope UNIQUE (provider, envelope_id),
+    CONSTRAINT chk_loan_agreements_disbursed CHECK (disbursed_at IS NULL OR status = 'COMPLETED')
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_loan_agreements_open ON loan_agreements (loan_id) WHERE status IN ('SENT', 'COMPLETED');
+CREATE INDEX IF NOT EXISTS idx_loan_agreements_loan_id ON loan_agreements (loan_id, sent_at);
+
+CREATE TABLE loan_agreements_archive (LIKE loan_agreements);
+CREATE INDEX IF NOT EXISTS idx_loan_agreements_archive_loan_id ON loan_agreements_archive (loan_id);
+
+-- +migrate Down
+
+DROP TABLE IF EXISTS loan_agreements_archive;
+DROP TABLE IF EXISTS loan_agreements;
@@ -0,0 +1,29 @@
+-- +migrate Up
+
+-- The shape of the loan's schedule, set by its product when it is booked and
+-- never changed: a rebuild regenerates the schedule from it. Interest-only
+-- loans repay no principal in their first interest_only_weeks installments;
+-- balloon loans leave balloon_amount of principal to the last one.
+ALTER TABLE loans ADD COLUMN schedule_type VARCHAR(16) NOT NULL DEFAULT 'STANDARD'
+    CHECK (schedule_type IN ('STANDARD', 'INTEREST_ONLY', 'BALLOON'));
+ALTER TABLE loans ADD COLUMN interest_only_weeks INT NOT NULL DEFAULT 0 CHECK (interest_only_weeks >= 0);
+ALTER TABLE loans ADD COLUMN balloon_amount DECIMAL(15, 2) NOT NULL DEFAULT 0 CHECK (balloon_amount >= 0);
+ALTER TABLE loans ADD CONSTRAINT chk_loans_schedule_structure CHECK (
+    (schedule_type = 'INTEREST_ONLY' AND interest_only_weeks > 0 AND interest_only_weeks < term_weeks AND balloon_amount = 0)
+    OR (schedule_type = 'BALLOON' AND interest_only_weeks = 0 AND balloon_amount > 0 AND balloon_amount < principal_amount)
+    OR (schedule_type = 'STANDARD' AND interest_only_weeks = 0 AND balloon_amount = 0)
+);
+
+ALTER TABLE loans_archive ADD COLUMN schedule_type VARCHAR(16) NOT NULL DEFAULT 'STANDARD';
+ALTER TABLE loans_archive ADD COLUMN interest_only_weeks INT NOT NULL DEFAULT 0;
+ALTER TABLE loans_archive ADD COLUMN balloon_amount DECIMAL(15, 2) NOT NULL DEFAULT 0;
+
+-- +migrate Down
+
+ALTER TABLE loans_archive DROP COLUMN IF EXISTS balloon_amount;
+ALTER TABLE loans_archive DROP COLUMN IF EXISTS interest_only_weeks;
+ALTER TABLE loans_archive DROP COLUMN IF EXISTS schedule_type;
+ALTER TABLE loans DROP CONSTRAINT IF EXISTS chk_loans_schedule_structure;
+ALTER TABLE loans DROP COLUMN IF EXISTS balloon_amount;
+ALTER TABLE loans DROP COLUMN IF EXISTS interest_only_weeks;
+ALTER TABLE loans DROP COLUMN IF EXISTS schedule_type;
@@ -0,0 +1,16 @@
+-- +migrate Up
+
+-- Reports read the archive tables alongside the live ones so that a period
+-- keeps its totals after its loans are archived. These indexes serve their
+-- date ranges.
+CREATE INDEX IF NOT EXISTS idx_payments_archive_paid_at ON payments_archive (paid_at);
+CREATE INDEX IF NOT EXISTS idx_prepayments_archive_paid_at ON prepayments_archive (paid_at);
+CREATE INDEX IF NOT EXISTS idx_tax_lines_archive_created_at ON tax_lines_archive (created_at);
+CREATE INDEX IF NOT EXISTS idx_loan_status_snapshots_archive_date ON loan_status_snapshots_archive (snapshot_date, loan_id);
+
+-- +migrate Down
+
+DROP INDEX IF EXISTS idx_loan_status_snapshots_archive_date;
+DROP INDEX IF EXISTS idx_tax_lines_archive_created_at;
+DROP INDEX IF EXISTS idx_prepayments_archive_paid_at;
+DROP INDEX IF EXISTS idx_payments_archive_paid_at;
@@ -0,0 +1,18 @@
+-- +migrate Up
+
+-- Per-tenant overrides of configured values. A NULL column keeps the
+-- configured value. Until customers and loans carry a tenant, the only
+-- tenant is 'default'.
+CREATE TABLE IF NOT EXISTS tenant_settings (
+    tenant_id VARCHAR(64) PRIMARY KEY,
+    missed_payments INT NULL CHECK (missed_payments >= 1),
+    cure_payments INT NULL CHECK (cure_payments >= 1),
+    rate_limit_rps DOUBLE PRECISION NULL CHECK (rate_limit_rps > 0),
+    rate_limit_burst INT NULL CHECK (rate_limit_burst >= 1),
+    updated_by VARCHAR(255) NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+-- +migrate Down
+
+DROP TABLE IF EXISTS tenant_settings;
@@ -0,0 +1,21 @@
+-- +migrate Up
+
+-- Once every installment is paid, payments settle the fees still owed, each
+-- together with its tax. A ledger entry then names the fee it paid instead
+-- of an installment.
+ALTER TABLE fees ADD COLUMN paid_at TIMESTAMPTZ NULL;
+ALTER TABLE fees_archive ADD COLUMN paid_at TIMESTAMPTZ NULL;
+
+ALTER TABLE payments ALTER COLUMN schedule_id DROP NOT NULL;
+ALTER TABLE payments ADD COLUMN fee_id BIGINT NULL REFERENCES fees(id) ON DELETE CASCADE;
+ALTER TABLE payments ADD CONSTRAINT chk_payments_paid_item CHECK ((schedule_id IS NULL) <> (fee_id IS NULL));
+ALTER TABLE payments_archive ALTER COLUMN schedule_id DROP NOT NULL;
+ALTER TABLE payments_archive ADD COLUMN fee_id BIGINT NULL;
+
+-- +migrate Down
+
+ALTER TABLE payments_archive DROP COLUMN IF EXISTS fee_id;
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS chk_payments_paid_item;
+ALTER TABLE payments DROP COLUMN IF EXISTS fee_id;
+ALTER TABLE fees_archive DROP COLUMN IF EXISTS paid_at;
+ALTER TABLE fees DROP COLUMN IF EXISTS paid_at;
@@ -0,0 +1,19 @@
+-- +migrate Up
+
+-- Every loan and customer belongs to a tenant, named by the tenant claim of
+-- the token that created it. Rows from before tenants, and rows batch jobs
+-- write, belong to 'default'.
+ALTER TABLE loans ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE loans_archive ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE customers ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+
+CREATE INDEX IF NOT EXISTS idx_loans_tenant_id ON loans (tenant_id, id);
+CREATE INDEX IF NOT EXISTS idx_customers_tenant_id ON customers (tenant_id, id);
+
+-- +migrate Down
+
+DROP INDEX IF EXISTS idx_customers_tenant_id;
+DROP INDEX IF EXISTS idx_loans_tenant_id;
+ALTER TABLE customers DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE loans_archive DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE loans DROP COLUMN IF EXISTS tenant_id;
@@ -0,0 +1,39 @@
+-- +migrate Up
+
+-- Row-level security keeps each tenant to its own loans and customers. With
+-- database.tenantEnforcement set to rls the service names the tenant of the
+-- request in app.current_tenant on every connection it uses. A session that
+-- names none, such as a batch job, a migration or psql, sees every row.
+-- FORCE applies the policies to the table owner too, which the service
+-- usually connects as; superusers and BYPASSRLS roles still skip them.
+ALTER TABLE loans ENABLE ROW LEVEL SECURITY;
+ALTER TABLE loans FORCE ROW LEVEL SECURITY;
+CREATE POLICY loans_tenant_isolation ON loans
+    USING (COALESCE(current_setting('app.current_tenant', true), '') IN ('', tenant_id))
+    WITH CHECK (COALESCE(current_setting('app.current_tenant', true), '') IN ('', tenant_id));
+
+ALTER TABLE loans_archive ENABLE ROW LEVEL SECURITY;
+ALTER TABLE loans_archive FORCE ROW LEVEL SECURITY;
+CREATE POLICY loans_archive_tenant_isolation ON loans_archive
+    USING (COALESCE(current_setting('app.current_tenant', true), '') IN ('', tenant_id))
+    WITH CHECK (COALESCE(current_setting('app.current_tenant', true), '') IN ('', tenant_id));
+
+ALTER TABLE customers ENABLE ROW LEVEL SECURITY;
+ALTER TABLE customers FORCE ROW LEVEL SECURITY;
+CREATE POLICY customers_tenant_isolation ON customers
+    USING (COALESCE(current_setting('app.current_tenant', true), '') IN ('', tenant_id))
+    WITH CHECK (COALESCE(current_setting('app.current_tenant', true), '') IN ('', tenant_id));
+
+-- +migrate Down
+
+DROP POLICY IF EXISTS customers_tenant_isolation ON customers;
+ALTER TABLE customers NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE customers DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS loans_archive_tenant_isolation ON loans_archive;
+ALTER TABLE loans_archive NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE loans_archive DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS loans_tenant_isolation ON loans;
+ALTER TABLE loans NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE loans DISABLE ROW LEVEL SECURITY;
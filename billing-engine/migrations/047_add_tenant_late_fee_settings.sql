@@ -0,0 +1,20 @@
+-- +migrate Up
+
+-- Tenants can override the grace period before an installment counts as
+-- missed, the late fee charged for it and how long a reminder stays worth
+-- sending. The late fee is a fee type of its own.
+ALTER TABLE tenant_settings ADD COLUMN grace_days INT NULL CHECK (grace_days >= 0);
+ALTER TABLE tenant_settings ADD COLUMN late_fee DECIMAL(15, 2) NULL CHECK (late_fee >= 0);
+ALTER TABLE tenant_settings ADD COLUMN reminder_window_days INT NULL CHECK (reminder_window_days >= 0);
+
+ALTER TABLE fees DROP CONSTRAINT IF EXISTS fees_fee_type_check;
+ALTER TABLE fees ADD CONSTRAINT fees_fee_type_check CHECK (fee_type IN ('PROCESSING', 'BOUNCE', 'LEGAL', 'LATE'));
+
+-- +migrate Down
+
+DELETE FROM fees WHERE fee_type = 'LATE';
+ALTER TABLE fees DROP CONSTRAINT IF EXISTS fees_fee_type_check;
+ALTER TABLE fees ADD CONSTRAINT fees_fee_type_check CHECK (fee_type IN ('PROCESSING', 'BOUNCE', 'LEGAL'));
+ALTER TABLE tenant_settings DROP COLUMN IF EXISTS reminder_window_days;
+ALTER TABLE tenant_settings DROP COLUMN IF EXISTS late_fee;
+ALTER TABLE tenant_settings DROP COLUMN IF EXISTS grace_days;
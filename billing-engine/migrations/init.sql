@@ -89,3 +89,1188 @@ CREATE TRIGGER set_timestamp_customers
 BEFORE UPDATE ON customers
 FOR EACH ROW
 EXECUTE FUNCTION trigger_set_timestamp();
+
+-- +migrate Up
+
+-- Reference assigned to the customer by the upstream system it was imported from
+ALTER TABLE customers ADD COLUMN external_ref VARCHAR(64) NULL;
+
+-- Imports deduplicate on this reference (allows multiple NULLs)
+ALTER TABLE customers ADD CONSTRAINT uq_customers_external_ref UNIQUE (external_ref);
+
+
+-- +migrate Up
+
+-- Reference assigned to the loan by the integrating system
+ALTER TABLE loans ADD COLUMN external_ref VARCHAR(64) NULL;
+
+-- Ensure a reference identifies at most one loan (allows multiple NULLs)
+ALTER TABLE loans ADD CONSTRAINT uq_loans_external_ref UNIQUE (external_ref);
+
+
+-- +migrate Up
+
+-- Public identifiers exposed by the API; the bigint keys stay internal.
+-- Existing rows are backfilled by the column default.
+ALTER TABLE customers ADD COLUMN public_id UUID NOT NULL DEFAULT gen_random_uuid();
+ALTER TABLE customers ADD CONSTRAINT uq_customers_public_id UNIQUE (public_id);
+
+ALTER TABLE loans ADD COLUMN public_id UUID NOT NULL DEFAULT gen_random_uuid();
+ALTER TABLE loans ADD CONSTRAINT uq_loans_public_id UNIQUE (public_id);
+
+
+-- +migrate Up
+
+-- Free-text notes recorded by staff against exactly one loan or customer
+CREATE TABLE notes (
+    id BIGSERIAL PRIMARY KEY,
+    loan_id BIGINT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    customer_id BIGINT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    author VARCHAR(255) NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_notes_subject CHECK ((loan_id IS NULL) <> (customer_id IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_notes_loan_id ON notes (loan_id) WHERE loan_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_notes_customer_id ON notes (customer_id) WHERE customer_id IS NOT NULL;
+
+-- Metadata of files kept in object storage; storage_key locates the object
+CREATE TABLE attachments (
+    id BIGSERIAL PRIMARY KEY,
+    loan_id BIGINT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    customer_id BIGINT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    file_name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    size_bytes BIGINT NOT NULL CHECK (size_bytes >= 0),
+    storage_key VARCHAR(512) NOT NULL,
+    uploaded_by VARCHAR(255) NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT uq_attachments_storage_key UNIQUE (storage_key),
+    CONSTRAINT chk_attachments_subject CHECK ((loan_id IS NULL) <> (customer_id IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachments_loan_id ON attachments (loan_id) WHERE loan_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_attachments_customer_id ON attachments (customer_id) WHERE customer_id IS NOT NULL;
+
+
+-- +migrate Up
+
+-- One row per loan per day, written by the snapshot job, so the book can be
+-- reported as it stood on a past date
+CREATE TABLE loan_status_snapshots (
+    snapshot_date DATE NOT NULL,
+    loan_id BIGINT NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('ACTIVE', 'PAID_OFF', 'DELINQUENT')),
+    outstanding DECIMAL(15, 2) NOT NULL CHECK (outstanding >= 0),
+    dpd INT NOT NULL DEFAULT 0 CHECK (dpd >= 0), -- days past due of the oldest unpaid installment
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (loan_id, snapshot_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_loan_status_snapshots_date ON loan_status_snapshots (snapshot_date);
+
+
+-- +migrate Up
+
+-- Row versions of loans and loan_schedule, kept by triggers so that every
+-- write is captured, including ones made outside the application. Each row
+-- holds the full record as JSONB and is valid from valid_from until valid_to;
+-- the current version has valid_to NULL. clock_timestamp() keeps several
+-- changes within one transaction in order.
+CREATE TABLE loans_history (
+    history_id BIGSERIAL PRIMARY KEY,
+    loan_id BIGINT NOT NULL,
+    operation CHAR(1) NOT NULL CHECK (operation IN ('I', 'U')),
+    row_data JSONB NOT NULL,
+    valid_from TIMESTAMPTZ NOT NULL,
+    valid_to TIMESTAMPTZ NULL
+);
+CREATE INDEX idx_loans_history_loan_id_valid_from ON loans_history (loan_id, valid_from);
+
+CREATE TABLE loan_schedule_history (
+    history_id BIGSERIAL PRIMARY KEY,
+    schedule_id BIGINT NOT NULL,
+    loan_id BIGINT NOT NULL,
+    operation CHAR(1) NOT NULL CHECK (operation IN ('I', 'U')),
+    row_data JSONB NOT NULL,
+    valid_from TIMESTAMPTZ NOT NULL,
+    valid_to TIMESTAMPTZ NULL
+);
+CREATE INDEX idx_loan_schedule_history_loan_id_valid_from ON loan_schedule_history (loan_id, valid_from);
+CREATE INDEX idx_loan_schedule_history_schedule_id ON loan_schedule_history (schedule_id) WHERE valid_to IS NULL;
+
+CREATE OR REPLACE FUNCTION record_loans_history()
+RETURNS TRIGGER AS $$
+DECLARE
+  changed_at TIMESTAMPTZ := clock_timestamp();
+BEGIN
+  IF TG_OP IN ('UPDATE', 'DELETE') THEN
+    UPDATE loans_history SET valid_to = changed_at
+    WHERE loan_id = OLD.id AND valid_to IS NULL;
+  END IF;
+  IF TG_OP IN ('INSERT', 'UPDATE') THEN
+    INSERT INTO loans_history (loan_id, operation, row_data, valid_from)
+    VALUES (NEW.id, LEFT(TG_OP, 1), to_jsonb(NEW), changed_at);
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION record_loan_schedule_history()
+RETURNS TRIGGER AS $$
+DECLARE
+  changed_at TIMESTAMPTZ := clock_timestamp();
+BEGIN
+  IF TG_OP IN ('UPDATE', 'DELETE') THEN
+    UPDATE loan_schedule_history SET valid_to = changed_at
+    WHERE schedule_id = OLD.id AND valid_to IS NULL;
+  END IF;
+  IF TG_OP IN ('INSERT', 'UPDATE') THEN
+    INSERT INTO loan_schedule_history (schedule_id, loan_id, operation, row_data, valid_from)
+    VALUES (NEW.id, NEW.loan_id, LEFT(TG_OP, 1), to_jsonb(NEW), changed_at);
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER record_history_loans
+AFTER INSERT OR UPDATE OR DELETE ON loans
+FOR EACH ROW
+EXECUTE FUNCTION record_loans_history();
+
+CREATE TRIGGER record_history_loan_schedule
+AFTER INSERT OR UPDATE OR DELETE ON loan_schedule
+FOR EACH ROW
+EXECUTE FUNCTION record_loan_schedule_history();
+
+-- Existing rows start their history at their last update.
+INSERT INTO loans_history (loan_id, operation, row_data, valid_from)
+SELECT id, 'I', to_jsonb(loans), updated_at FROM loans;
+
+INSERT INTO loan_schedule_history (schedule_id, loan_id, operation, row_data, valid_from)
+SELECT id, loan_id, 'I', to_jsonb(loan_schedule), updated_at FROM loan_schedule;
+
+-- +migrate Up
+
+-- The application now writes updated_at itself from its clock. Only fill it
+-- in when an UPDATE leaves it unchanged, so writes made outside the
+-- application are still stamped.
+CREATE OR REPLACE FUNCTION trigger_set_timestamp()
+RETURNS TRIGGER AS $$
+BEGIN
+  IF NEW.updated_at IS NOT DISTINCT FROM OLD.updated_at THEN
+    NEW.updated_at = NOW();
+  END IF;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- +migrate Up
+
+-- Customer authorisations to collect installments from a bank account
+CREATE TABLE mandates (
+    id BIGSERIAL PRIMARY KEY,
+    customer_id BIGINT NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    reference VARCHAR(35) NOT NULL,
+    account_holder VARCHAR(140) NOT NULL,
+    account_number VARCHAR(34) NOT NULL,
+    bank_code VARCHAR(11) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'ACTIVE',
+    signed_at DATE NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT uq_mandates_reference UNIQUE (reference),
+    CONSTRAINT chk_mandates_status CHECK (status IN ('ACTIVE', 'CANCELLED'))
+);
+
+-- A customer holds at most one active mandate
+CREATE UNIQUE INDEX IF NOT EXISTS uq_mandates_active_customer ON mandates (customer_id) WHERE status = 'ACTIVE';
+
+CREATE TRIGGER set_timestamp_mandates
+BEFORE UPDATE ON mandates
+FOR EACH ROW
+EXECUTE FUNCTION trigger_set_timestamp();
+
+-- One collection attempt of an installment under a mandate
+CREATE TABLE direct_debit_instructions (
+    id BIGSERIAL PRIMARY KEY,
+    mandate_id BIGINT NOT NULL REFERENCES mandates(id) ON DELETE CASCADE,
+    loan_id BIGINT NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    schedule_id BIGINT NOT NULL REFERENCES loan_schedule(id) ON DELETE CASCADE,
+    week_number INT NOT NULL CHECK (week_number > 0),
+    end_to_end_id VARCHAR(35) NOT NULL,
+    amount DECIMAL(15, 2) NOT NULL CHECK (amount > 0),
+    collection_date DATE NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+    batch_ref VARCHAR(35) NULL,
+    failure_reason TEXT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT uq_direct_debit_instructions_end_to_end_id UNIQUE (end_to_end_id),
+    CONSTRAINT chk_direct_debit_instructions_status
+        CHECK (status IN ('PENDING', 'EXPORTED', 'COLLECTED', 'FAILED', 'UNAPPLIED'))
+);
+
+-- Only failed instructions leave their installment open for another attempt
+CREATE UNIQUE INDEX IF NOT EXISTS uq_direct_debit_instructions_open_schedule
+    ON direct_debit_instructions (schedule_id) WHERE status <> 'FAILED';
+CREATE INDEX IF NOT EXISTS idx_direct_debit_instructions_status
+    ON direct_debit_instructions (status, collection_date);
+
+CREATE TRIGGER set_timestamp_direct_debit_instructions
+BEFORE UPDATE ON direct_debit_instructions
+FOR EACH ROW
+EXECUTE FUNCTION trigger_set_timestamp();
+
+-- +migrate Up
+
+-- Ledger of the payments applied to installments, for reconciliation by
+-- channel
+CREATE TABLE payments (
+    id BIGSERIAL PRIMARY KEY,
+    loan_id BIGINT NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    schedule_id BIGINT NOT NULL REFERENCES loan_schedule(id) ON DELETE CASCADE,
+    amount DECIMAL(15, 2) NOT NULL CHECK (amount > 0),
+    channel VARCHAR(20) NOT NULL DEFAULT 'UNSPECIFIED',
+    reference VARCHAR(100) NULL,
+    collector_id VARCHAR(64) NULL,
+    paid_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    -- NULL references never collide, so only identified payments are guarded
+    -- against being posted twice
+    CONSTRAINT uq_payments_channel_reference UNIQUE (channel, reference),
+    CONSTRAINT chk_payments_channel
+        CHECK (channel IN ('CASH', 'BANK_TRANSFER', 'GATEWAY', 'DIRECT_DEBIT', 'UNSPECIFIED'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_payments_paid_at ON payments (paid_at);
+CREATE INDEX IF NOT EXISTS idx_payments_loan_id ON payments (loan_id);
+
+-- Installments paid before the ledger existed, without their channel
+INSERT INTO payments (loan_id, schedule_id, amount, channel, paid_at, created_at)
+SELECT loan_id, id, paid_amount, 'UNSPECIFIED', COALESCE(payment_date, updated_at), updated_at
+FROM loan_schedule
+WHERE status = 'PAID' AND paid_amount > 0;
+
+-- +migrate Up
+
+-- Days the oldest unpaid installment is overdue, kept up to date by the
+-- nightly delinquency job. Loans start at 0 and are filled in by its next run.
+ALTER TABLE loans ADD COLUMN days_past_due INT NOT NULL DEFAULT 0 CHECK (days_past_due >= 0);
+
+-- Collections queries ask for loans at or above a DPD threshold; current loans
+-- are the bulk of the book and are left out of the index.
+CREATE INDEX IF NOT EXISTS idx_loans_days_past_due ON loans (days_past_due) WHERE days_past_due > 0;
+
+-- +migrate Up
+
+-- Rules routing past-due loans to collectors, tried by priority then ID
+CREATE TABLE collection_rules (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    collector VARCHAR(64) NOT NULL,
+    bucket VARCHAR(10) NULL,
+    region VARCHAR(100) NULL,
+    min_outstanding DECIMAL(15, 2) NULL CHECK (min_outstanding >= 0),
+    max_outstanding DECIMAL(15, 2) NULL,
+    priority INT NOT NULL DEFAULT 0,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_collection_rules_bucket CHECK (bucket IN ('1-30', '31-60', '61-90', '90+')),
+    CONSTRAINT chk_collection_rules_outstanding CHECK (max_outstanding >= min_outstanding)
+);
+
+CREATE TRIGGER set_timestamp_collection_rules
+BEFORE UPDATE ON collection_rules
+FOR EACH ROW
+EXECUTE FUNCTION trigger_set_timestamp();
+
+-- A loan in the queue of a collector, from assignment until it is cured or
+-- paid off
+CREATE TABLE collection_assignments (
+    id BIGSERIAL PRIMARY KEY,
+    loan_id BIGINT NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    customer_id BIGINT NULL REFERENCES customers(id) ON DELETE SET NULL,
+    collector VARCHAR(64) NOT NULL,
+    rule_id BIGINT NULL REFERENCES collection_rules(id) ON DELETE SET NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'OPEN',
+    resolution VARCHAR(20) NULL,
+    assigned_at TIMESTAMPTZ NOT NULL,
+    resolved_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_collection_assignments_status CHECK (status IN ('OPEN', 'RESOLVED')),
+    CONSTRAINT chk_collection_assignments_resolution CHECK (resolution IN ('CURED', 'PAID_OFF')),
+    CONSTRAINT chk_collection_assignments_resolved CHECK ((status = 'RESOLVED') = (resolved_at IS NOT NULL))
+);
+
+-- A loan is in at most one queue at a time
+CREATE UNIQUE INDEX IF NOT EXISTS uq_collection_assignments_open_loan ON collection_assignments (loan_id) WHERE status = 'OPEN';
+CREATE INDEX IF NOT EXISTS idx_collection_assignments_open_collector ON collection_assignments (collector) WHERE status = 'OPEN';
+
+CREATE TRIGGER set_timestamp_collection_assignments
+BEFORE UPDATE ON collection_assignments
+FOR EACH ROW
+EXECUTE FUNCTION trigger_set_timestamp();
+
+-- What collectors did about their assigned loans, including reassignments
+CREATE TABLE collection_actions (
+    id BIGSERIAL PRIMARY KEY,
+    assignment_id BIGINT NOT NULL REFERENCES collection_assignments(id) ON DELETE CASCADE,
+    loan_id BIGINT NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    collector VARCHAR(64) NULL,
+    type VARCHAR(20) NOT NULL,
+    note TEXT NULL,
+    promised_amount DECIMAL(15, 2) NULL CHECK (promised_amount > 0),
+    promised_date DATE NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_collection_actions_type
+        CHECK (type IN ('CALL', 'SMS', 'VISIT', 'PROMISE_TO_PAY', 'NOTE', 'REASSIGNED'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_collection_actions_assignment_id ON collection_actions (assignment_id);
+
+-- +migrate Up
+
+-- Every event handed to the broker, kept so that consumers that were down can
+-- be backfilled. payload is JSON rather than JSONB so that a replay sends the
+-- original bytes. published_at stays NULL while the broker has not accepted
+-- the event.
+CREATE TABLE event_log (
+    id BIGSERIAL PRIMARY KEY,
+    event_id VARCHAR(64) NOT NULL UNIQUE,
+    event_type VARCHAR(64) NOT NULL,
+    entity_id BIGINT NOT NULL,
+    payload JSON NOT NULL,
+    occurred_at TIMESTAMPTZ NOT NULL,
+    published_at TIMESTAMPTZ NULL,
+    replay_count INT NOT NULL DEFAULT 0,
+    last_replayed_at TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_log_entity_id ON event_log (entity_id);
+CREATE INDEX IF NOT EXISTS idx_event_log_occurred_at ON event_log (occurred_at);
+CREATE INDEX IF NOT EXISTS idx_event_log_unpublished ON event_log (id) WHERE published_at IS NULL;
+
+-- +migrate Up
+
+-- One row per customer with the state of their current loan, kept by the
+-- summary projector so that GET /customers/{id}/summary reads a single row.
+-- Rows are recomputed from customers, loans and loan_schedule and can be
+-- rebuilt at any time.
+CREATE TABLE customer_loan_summary (
+    customer_id BIGINT PRIMARY KEY REFERENCES customers(id) ON DELETE CASCADE,
+    customer_public_id UUID NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    address TEXT NOT NULL,
+    active BOOLEAN NOT NULL,
+    is_delinquent BOOLEAN NOT NULL,
+    loan_id BIGINT NULL,
+    loan_public_id UUID NULL,
+    loan_status VARCHAR(20) NULL,
+    principal_amount DECIMAL(15, 2) NOT NULL DEFAULT 0,
+    total_loan_amount DECIMAL(15, 2) NOT NULL DEFAULT 0,
+    total_paid DECIMAL(15, 2) NOT NULL DEFAULT 0,
+    outstanding DECIMAL(15, 2) NOT NULL DEFAULT 0,
+    installments_paid INT NOT NULL DEFAULT 0,
+    installments_total INT NOT NULL DEFAULT 0,
+    next_due_date DATE NULL,
+    next_due_amount DECIMAL(15, 2) NOT NULL DEFAULT 0,
+    days_past_due INT NOT NULL DEFAULT 0,
+    refreshed_at TIMESTAMPTZ NOT NULL
+);
+
+-- Payments name only the loan
+CREATE INDEX IF NOT EXISTS idx_customer_loan_summary_loan_id ON customer_loan_summary (loan_id) WHERE loan_id IS NOT NULL;
+
+-- +migrate Up
+
+-- Administrative holds placed on loans. While a hold is open the loan takes
+-- no payments and is left out of direct-debit collection. Released holds stay
+-- as history.
+CREATE TABLE loan_holds (
+    id BIGSERIAL PRIMARY KEY,
+    loan_id BIGINT NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL CHECK (reason <> ''),
+    placed_by VARCHAR(64) NULL,
+    placed_at TIMESTAMPTZ NOT NULL,
+    released_by VARCHAR(64) NULL,
+    released_at TIMESTAMPTZ NULL
+);
+
+-- A loan has at most one open hold
+CREATE UNIQUE INDEX IF NOT EXISTS uq_loan_holds_open_loan ON loan_holds (loan_id) WHERE released_at IS NULL;
+
+-- +migrate Up
+
+-- Violations the nightly integrity job found on its last run. A violation
+-- that is still there on the next run keeps its row and first_seen_at; one
+-- that is gone is deleted.
+CREATE TABLE integrity_findings (
+    id BIGSERIAL PRIMARY KEY,
+    check_name VARCHAR(40) NOT NULL,
+    entity_type VARCHAR(20) NOT NULL,
+    entity_id BIGINT NOT NULL,
+    detail TEXT NOT NULL,
+    first_seen_at TIMESTAMPTZ NOT NULL,
+    last_seen_at TIMESTAMPTZ NOT NULL,
+    CONSTRAINT uq_integrity_findings_check_entity UNIQUE (check_name, entity_id)
+);
+
+-- +migrate Up
+
+-- Paid-off loans past the retention period are moved out of the live tables.
+-- archived_loans is the catalogue; every other table below mirrors the
+-- columns of its live table, without keys or constraints, so that rows move
+-- back and forth with INSERT ... SELECT *. A migration that adds a column to
+-- one of the live tables adds it to the archive table too.
+CREATE TABLE archived_loans (
+    loan_id BIGINT PRIMARY KEY,
+    public_id UUID NOT NULL,
+    customer_id BIGINT NULL, -- the customer the loan was assigned to
+    completed_at TIMESTAMPTZ NOT NULL,
+    archived_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_archived_loans_archived_at ON archived_loans (archived_at);
+
+CREATE TABLE loans_archive (LIKE loans);
+CREATE TABLE loan_schedule_archive (LIKE loan_schedule);
+CREATE TABLE payments_archive (LIKE payments);
+CREATE TABLE direct_debit_instructions_archive (LIKE direct_debit_instructions);
+CREATE TABLE loan_status_snapshots_archive (LIKE loan_status_snapshots);
+CREATE TABLE collection_assignments_archive (LIKE collection_assignments);
+CREATE TABLE collection_actions_archive (LIKE collection_actions);
+CREATE TABLE loan_holds_archive (LIKE loan_holds);
+CREATE TABLE notes_archive (LIKE notes);
+CREATE TABLE attachments_archive (LIKE attachments);
+
+CREATE INDEX IF NOT EXISTS idx_loans_archive_id ON loans_archive (id);
+CREATE INDEX IF NOT EXISTS idx_loan_schedule_archive_loan_id ON loan_schedule_archive (loan_id);
+CREATE INDEX IF NOT EXISTS idx_payments_archive_loan_id ON payments_archive (loan_id);
+CREATE INDEX IF NOT EXISTS idx_direct_debit_instructions_archive_loan_id ON direct_debit_instructions_archive (loan_id);
+CREATE INDEX IF NOT EXISTS idx_loan_status_snapshots_archive_loan_id ON loan_status_snapshots_archive (loan_id);
+CREATE INDEX IF NOT EXISTS idx_collection_assignments_archive_loan_id ON collection_assignments_archive (loan_id);
+CREATE INDEX IF NOT EXISTS idx_collection_actions_archive_loan_id ON collection_actions_archive (loan_id);
+CREATE INDEX IF NOT EXISTS idx_loan_holds_archive_loan_id ON loan_holds_archive (loan_id);
+CREATE INDEX IF NOT EXISTS idx_notes_archive_loan_id ON notes_archive (loan_id);
+CREATE INDEX IF NOT EXISTS idx_attachments_archive_loan_id ON attachments_archive (loan_id);
+
+-- +migrate Up
+
+-- loan_schedule is partitioned by ranges of 100000 loan IDs, so that the
+-- schedule of one loan lives in one partition, and payments by calendar
+-- month (UTC) of paid_at, which the collections reports filter on. The
+-- existing tables are not copied: each becomes the first partition of its new
+-- parent, covering every key up to the end of its current range. The
+-- PartitionMaintenance job creates the partitions that follow. A row no
+-- partition covers goes to the default partition rather than failing.
+
+-- Primary and unique keys of a partitioned table must include the partition
+-- key, so the keys that reference loan_schedule(id) now include loan_id.
+ALTER TABLE payments DROP CONSTRAINT payments_schedule_id_fkey;
+ALTER TABLE direct_debit_instructions DROP CONSTRAINT direct_debit_instructions_schedule_id_fkey;
+
+ALTER TABLE loan_schedule RENAME TO loan_schedule_legacy;
+ALTER TABLE loan_schedule_legacy DROP CONSTRAINT loan_schedule_pkey;
+ALTER TABLE loan_schedule_legacy ADD CONSTRAINT loan_schedule_legacy_pkey PRIMARY KEY (loan_id, id);
+ALTER TABLE loan_schedule_legacy RENAME CONSTRAINT loan_schedule_loan_id_week_number_key TO loan_schedule_legacy_loan_id_week_number_key;
+ALTER INDEX idx_loan_schedule_loan_id_status RENAME TO idx_loan_schedule_legacy_loan_id_status;
+ALTER INDEX idx_loan_schedule_due_date RENAME TO idx_loan_schedule_legacy_due_date;
+ALTER INDEX idx_loan_schedule_loan_id_due_date_status RENAME TO idx_loan_schedule_legacy_loan_id_due_date_status;
+-- The parent's triggers fire for every partition.
+DROP TRIGGER set_timestamp_loan_schedule ON loan_schedule_legacy;
+DROP TRIGGER record_history_loan_schedule ON loan_schedule_legacy;
+
+CREATE TABLE loan_schedule (
+    id BIGINT NOT NULL DEFAULT nextval('loan_schedule_id_seq'),
+    loan_id BIGINT NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    week_number INT NOT NULL CHECK (week_number > 0),
+    due_date DATE NOT NULL,
+    due_amount DECIMAL(15, 2) NOT NULL CHECK (due_amount >= 0),
+    paid_amount DECIMAL(15, 2) DEFAULT 0.00 CHECK (paid_amount >= 0),
+    payment_date TIMESTAMPTZ NULL,
+    status payment_status NOT NULL DEFAULT 'PENDING',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (loan_id, id),
+    UNIQUE (loan_id, week_number)
+) PARTITION BY RANGE (loan_id);
+ALTER SEQUENCE loan_schedule_id_seq OWNED BY loan_schedule.id;
+
+CREATE INDEX idx_loan_schedule_loan_id_status ON loan_schedule(loan_id, status);
+CREATE INDEX idx_loan_schedule_due_date ON loan_schedule(due_date);
+CREATE INDEX idx_loan_schedule_loan_id_due_date_status ON loan_schedule(loan_id, due_date, status);
+
+CREATE TRIGGER set_timestamp_loan_schedule
+BEFORE UPDATE ON loan_schedule
+FOR EACH ROW
+EXECUTE FUNCTION trigger_set_timestamp();
+
+CREATE TRIGGER record_history_loan_schedule
+AFTER INSERT OR UPDATE OR DELETE ON loan_schedule
+FOR EACH ROW
+EXECUTE FUNCTION record_loan_schedule_history();
+
+DO $$
+DECLARE
+  bound BIGINT;
+BEGIN
+  SELECT (GREATEST((SELECT last_value FROM loans_id_seq), COALESCE(MAX(loan_id), 0)) / 100000 + 1) * 100000
+  INTO bound FROM loan_schedule_legacy;
+  EXECUTE format('ALTER TABLE loan_schedule ATTACH PARTITION loan_schedule_legacy FOR VALUES FROM (MINVALUE) TO (%s)', bound);
+  EXECUTE format('CREATE TABLE loan_schedule_p%s PARTITION OF loan_schedule FOR VALUES FROM (%s) TO (%s)',
+    bound / 100000, bound, bound + 100000);
+END;
+$$;
+CREATE TABLE loan_schedule_default PARTITION OF loan_schedule DEFAULT;
+
+ALTER TABLE direct_debit_instructions ADD CONSTRAINT direct_debit_instructions_schedule_id_fkey
+    FOREIGN KEY (loan_id, schedule_id) REFERENCES loan_schedule (loan_id, id) ON DELETE CASCADE;
+
+ALTER TABLE payments RENAME TO payments_legacy;
+ALTER TABLE payments_legacy DROP CONSTRAINT payments_pkey;
+ALTER TABLE payments_legacy ADD CONSTRAINT payments_legacy_pkey PRIMARY KEY (id, paid_at);
+ALTER TABLE payments_legacy DROP CONSTRAINT uq_payments_channel_reference;
+ALTER INDEX idx_payments_paid_at RENAME TO idx_payments_legacy_paid_at;
+ALTER INDEX idx_payments_loan_id RENAME TO idx_payments_legacy_loan_id;
+
+CREATE TABLE payments (
+    id BIGINT NOT NULL DEFAULT nextval('payments_id_seq'),
+    loan_id BIGINT NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    schedule_id BIGINT NOT NULL,
+    amount DECIMAL(15, 2) NOT NULL CHECK (amount > 0),
+    channel VARCHAR(20) NOT NULL DEFAULT 'UNSPECIFIED',
+    reference VARCHAR(100) NULL,
+    collector_id VARCHAR(64) NULL,
+    paid_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (id, paid_at),
+    CONSTRAINT payments_schedule_id_fkey
+        FOREIGN KEY (loan_id, schedule_id) REFERENCES loan_schedule (loan_id, id) ON DELETE CASCADE,
+    CONSTRAINT chk_payments_channel
+        CHECK (channel IN ('CASH', 'BANK_TRANSFER', 'GATEWAY', 'DIRECT_DEBIT', 'UNSPECIFIED'))
+) PARTITION BY RANGE (paid_at);
+ALTER SEQUENCE payments_id_seq OWNED BY payments.id;
+
+CREATE INDEX IF NOT EXISTS idx_payments_paid_at ON payments (paid_at);
+CREATE INDEX IF NOT EXISTS idx_payments_loan_id ON payments (loan_id);
+
+-- A unique constraint on payments would have to include paid_at and so
+-- would let a reference be posted again in another month. The references of
+-- identified payments are kept here instead, under the constraint name the
+-- repositories map to a duplicate payment.
+CREATE TABLE payment_references (
+    channel VARCHAR(20) NOT NULL,
+    reference VARCHAR(100) NOT NULL,
+    payment_id BIGINT NOT NULL,
+    CONSTRAINT uq_payments_channel_reference PRIMARY KEY (channel, reference)
+);
+
+INSERT INTO payment_references (channel, reference, payment_id)
+SELECT channel, reference, id FROM payments_legacy WHERE reference IS NOT NULL;
+
+CREATE OR REPLACE FUNCTION record_payment_reference()
+RETURNS TRIGGER AS $$
+BEGIN
+  IF TG_OP IN ('UPDATE', 'DELETE') AND OLD.reference IS NOT NULL THEN
+    DELETE FROM payment_references WHERE channel = OLD.channel AND reference = OLD.reference;
+  END IF;
+  IF TG_OP IN ('INSERT', 'UPDATE') AND NEW.reference IS NOT NULL THEN
+    INSERT INTO payment_references (channel, reference, payment_id) VALUES (NEW.channel, NEW.reference, NEW.id);
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER record_payment_reference
+AFTER INSERT OR UPDATE OR DELETE ON payments
+FOR EACH ROW
+EXECUTE FUNCTION record_payment_reference();
+
+DO $$
+DECLARE
+  month_start TIMESTAMP;
+BEGIN
+  SELECT date_trunc('month', GREATEST(now(), COALESCE(MAX(paid_at), now())) AT TIME ZONE 'UTC') + INTERVAL '1 month'
+  INTO month_start FROM payments_legacy;
+  EXECUTE format('ALTER TABLE payments ATTACH PARTITION payments_legacy FOR VALUES FROM (MINVALUE) TO (%L)',
+    month_start || '+00');
+  EXECUTE format('CREATE TABLE payments_%s PARTITION OF payments FOR VALUES FROM (%L) TO (%L)',
+    to_char(month_start, '"y"YYYY"m"MM'), month_start || '+00', (month_start + INTERVAL '1 month') || '+00');
+END;
+$$;
+CREATE TABLE payments_default PARTITION OF payments DEFAULT;
+
+-- +migrate Up
+
+-- Async jobs: bulk uploads handed off by the API, event replays and the runs
+-- of the batch jobs. Every instance polls this table for work, claims a job
+-- with FOR UPDATE SKIP LOCKED and renews heartbeat_at while it runs it; a
+-- running job whose heartbeat is older than the lease is claimed again.
+-- Finished jobs are deleted after the retention.
+CREATE TABLE jobs (
+    id UUID PRIMARY KEY,
+    kind VARCHAR(64) NOT NULL,
+    status VARCHAR(16) NOT NULL CHECK (status IN ('QUEUED', 'RUNNING', 'SUCCEEDED', 'FAILED')),
+    -- Scheduled runs carry their job name and time, so that the instances
+    -- whose cron fires for the same run queue it once.
+    dedupe_key VARCHAR(128) NULL,
+    payload JSONB NULL,
+    result JSONB NULL,
+    error TEXT NULL,
+    total INTEGER NOT NULL DEFAULT 0,
+    processed INTEGER NOT NULL DEFAULT 0,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    worker VARCHAR(128) NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    started_at TIMESTAMPTZ NULL,
+    heartbeat_at TIMESTAMPTZ NULL,
+    finished_at TIMESTAMPTZ NULL,
+    CONSTRAINT uq_jobs_dedupe_key UNIQUE (dedupe_key)
+);
+
+-- Serves the claim: only unfinished jobs are indexed
+CREATE INDEX IF NOT EXISTS idx_jobs_unfinished ON jobs (created_at) WHERE status IN ('QUEUED', 'RUNNING');
+CREATE INDEX IF NOT EXISTS idx_jobs_finished_at ON jobs (finished_at) WHERE finished_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_jobs_kind_created_at ON jobs (kind, created_at);
+
+-- +migrate Up
+
+-- Communication preferences a customer has set. Customers without a row have
+-- no preferred channel or language and have opted out of nothing.
+-- notify-service keeps its own copy from customer.preferences.changed events.
+CREATE TABLE customer_preferences (
+    customer_id BIGINT PRIMARY KEY REFERENCES customers(id) ON DELETE CASCADE,
+    preferred_channel VARCHAR(16) NOT NULL DEFAULT '' CHECK (preferred_channel IN ('', 'sms', 'email')),
+    language VARCHAR(35) NOT NULL DEFAULT '',
+    marketing_opt_out BOOLEAN NOT NULL DEFAULT FALSE,
+    transactional_opt_out BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+-- +migrate Up
+
+-- Rate changes of variable-rate loans. From effective_from on, installments
+-- are charged rate instead of previous_rate; loans.interest_rate holds the
+-- latest rate and the schedule the recalculated amounts. A loan's changes
+-- take effect in the order they were made.
+CREATE TABLE rate_history (
+    id BIGSERIAL PRIMARY KEY,
+    loan_id BIGINT NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    previous_rate DECIMAL(5, 4) NOT NULL CHECK (previous_rate >= 0),
+    rate DECIMAL(5, 4) NOT NULL CHECK (rate >= 0),
+    effective_from DATE NOT NULL,
+    changed_by VARCHAR(64) NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    CONSTRAINT uq_rate_history_loan_effective_from UNIQUE (loan_id, effective_from)
+);
+
+CREATE TABLE rate_history_archive (LIKE rate_history);
+CREATE INDEX IF NOT EXISTS idx_rate_history_archive_loan_id ON rate_history_archive (loan_id);
+
+-- +migrate Up
+
+-- Payment holidays and promotional zero-interest windows. A payment holiday
+-- moves the installments due from starts_on on back by weeks weeks; a
+-- zero-interest window waives the interest of those due in the weeks from
+-- starts_on. The schedule and loans.total_loan_amount hold the result.
+-- Removed adjustments are kept with who removed them.
+CREATE TABLE schedule_adjustments (
+    id BIGSERIAL PRIMARY KEY,
+    loan_id BIGINT NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('PAYMENT_HOLIDAY', 'ZERO_INTEREST')),
+    starts_on DATE NOT NULL,
+    weeks INT NOT NULL CHECK (weeks > 0),
+    reason TEXT NOT NULL,
+    applied_by VARCHAR(64) NULL,
+    applied_at TIMESTAMPTZ NOT NULL,
+    removed_by VARCHAR(64) NULL,
+    removed_at TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_schedule_adjustments_loan_id ON schedule_adjustments (loan_id);
+
+CREATE TABLE schedule_adjustments_archive (LIKE schedule_adjustments);
+CREATE INDEX IF NOT EXISTS idx_schedule_adjustments_archive_loan_id ON schedule_adjustments_archive (loan_id);
+
+-- +migrate Up
+
+-- Lump sums paid against a loan's principal on top of its installments. The
+-- installments after after_week were reamortized to the terms on the row:
+-- each repays weekly_principal of principal, the last the rest, and either
+-- the term or the installment shrank as the borrower chose. weekly_principal
+-- is kept unrounded since the schedule is regenerated from it. The previous
+-- terms are kept for the audit trail.
+CREATE TABLE prepayments (
+    id BIGSERIAL PRIMARY KEY,
+    loan_id BIGINT NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    option VARCHAR(20) NOT NULL CHECK (option IN ('REDUCE_TERM', 'REDUCE_INSTALLMENT')),
+    amount DECIMAL(15, 2) NOT NULL CHECK (amount > 0),
+    after_week INT NOT NULL CHECK (after_week >= 0),
+    principal DECIMAL(15, 2) NOT NULL CHECK (principal > 0),
+    weekly_principal DOUBLE PRECISION NOT NULL CHECK (weekly_principal > 0),
+    previous_term_weeks INT NOT NULL,
+    previous_weekly_payment_amount DECIMAL(15, 2) NOT NULL,
+    previous_total_loan_amount DECIMAL(15, 2) NOT NULL,
+    term_weeks INT NOT NULL CHECK (term_weeks > after_week),
+    weekly_payment_amount DECIMAL(15, 2) NOT NULL,
+    total_loan_amount DECIMAL(15, 2) NOT NULL,
+    channel VARCHAR(20) NOT NULL DEFAULT 'UNSPECIFIED',
+    reference VARCHAR(100) NULL,
+    recorded_by VARCHAR(64) NULL,
+    paid_at TIMESTAMPTZ NOT NULL,
+    CONSTRAINT uq_prepayments_channel_reference UNIQUE (channel, reference),
+    CONSTRAINT chk_prepayments_channel
+        CHECK (channel IN ('CASH', 'BANK_TRANSFER', 'GATEWAY', 'DIRECT_DEBIT', 'UNSPECIFIED'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_prepayments_loan_id ON prepayments (loan_id);
+
+CREATE TABLE prepayments_archive (LIKE prepayments);
+CREATE INDEX IF NOT EXISTS idx_prepayments_archive_loan_id ON prepayments_archive (loan_id);
+
+-- +migrate Up
+
+-- Charges posted to a loan outside its schedule, typed by the fee catalog.
+-- A fee is owed on top of the installments until a waiver of it is
+-- approved.
+CREATE TABLE fees (
+    id BIGSERIAL PRIMARY KEY,
+    loan_id BIGINT NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    fee_type VARCHAR(20) NOT NULL CHECK (fee_type IN ('PROCESSING', 'BOUNCE', 'LEGAL')),
+    amount DECIMAL(15, 2) NOT NULL CHECK (amount > 0),
+    reason TEXT NOT NULL CHECK (reason <> ''),
+    posted_by VARCHAR(64) NULL,
+    posted_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_fees_loan_id ON fees (loan_id);
+
+-- Requests to write a fee off and the decision on each. loan_id repeats the
+-- fee's so the rows archive with the loan. Rejected requests stay as
+-- history.
+CREATE TABLE fee_waivers (
+    id BIGSERIAL PRIMARY KEY,
+    fee_id BIGINT NOT NULL REFERENCES fees(id) ON DELETE CASCADE,
+    loan_id BIGINT NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL CHECK (reason <> ''),
+    status VARCHAR(20) NOT NULL CHECK (status IN ('PENDING', 'APPROVED', 'REJECTED')),
+    requested_by VARCHAR(64) NULL,
+    requested_at TIMESTAMPTZ NOT NULL,
+    decided_by VARCHAR(64) NULL,
+    decided_at TIMESTAMPTZ NULL,
+    CONSTRAINT chk_fee_waivers_decided CHECK ((status = 'PENDING') = (decided_at IS NULL))
+);
+
+-- A fee has at most one waiver that is pending or approved
+CREATE UNIQUE INDEX IF NOT EXISTS uq_fee_waivers_open_fee ON fee_waivers (fee_id) WHERE status <> 'REJECTED';
+CREATE INDEX IF NOT EXISTS idx_fee_waivers_loan_id ON fee_waivers (loan_id);
+
+CREATE TABLE fees_archive (LIKE fees);
+CREATE INDEX IF NOT EXISTS idx_fees_archive_loan_id ON fees_archive (loan_id);
+CREATE TABLE fee_waivers_archive (LIKE fee_waivers);
+CREATE INDEX IF NOT EXISTS idx_fee_waivers_archive_loan_id ON fee_waivers_archive (loan_id);
+
+-- The tax charged on a fee, at the jurisdiction and rate in force when it
+-- was posted. A fee has at most one. loan_id repeats the fee's so the rows
+-- archive with the loan.
+CREATE TABLE tax_lines (
+    id BIGSERIAL PRIMARY KEY,
+    fee_id BIGINT NOT NULL UNIQUE REFERENCES fees(id) ON DELETE CASCADE,
+    loan_id BIGINT NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    jurisdiction VARCHAR(32) NOT NULL CHECK (jurisdiction <> ''),
+    rate DECIMAL(6, 4) NOT NULL CHECK (rate > 0 AND rate < 1),
+    taxable_amount DECIMAL(15, 2) NOT NULL CHECK (taxable_amount > 0),
+    amount DECIMAL(15, 2) NOT NULL CHECK (amount > 0),
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_tax_lines_loan_id ON tax_lines (loan_id);
+CREATE INDEX IF NOT EXISTS idx_tax_lines_created_at ON tax_lines (created_at);
+
+CREATE TABLE tax_lines_archive (LIKE tax_lines);
+CREATE INDEX IF NOT EXISTS idx_tax_lines_archive_loan_id ON tax_lines_archive (loan_id);
+
+-- The customer's latest credit bureau assessment. Score, grade and the time
+-- it was scored are set together, and are NULL until the first assessment.
+ALTER TABLE customers ADD COLUMN risk_score INTEGER NULL CHECK (risk_score BETWEEN 0 AND 1000);
+ALTER TABLE customers ADD COLUMN risk_grade VARCHAR(8) NULL;
+ALTER TABLE customers ADD COLUMN risk_scored_at TIMESTAMPTZ NULL;
+ALTER TABLE customers ADD CONSTRAINT chk_customers_risk_complete
+    CHECK ((risk_score IS NULL) = (risk_grade IS NULL) AND (risk_grade IS NULL) = (risk_scored_at IS NULL));
+
+-- The reminder ladder: what happens once a loan is days_past_due days past
+-- due. SMS and EMAIL remind the customer, CALL and LETTER are tasks for the
+-- collections team.
+CREATE TABLE escalation_steps (
+    days_past_due INT PRIMARY KEY CHECK (days_past_due BETWEEN 1 AND 3650),
+    action VARCHAR(10) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_escalation_steps_action CHECK (action IN ('SMS', 'EMAIL', 'CALL', 'LETTER'))
+);
+
+INSERT INTO escalation_steps (days_past_due, action) VALUES
+    (1, 'SMS'),
+    (7, 'EMAIL'),
+    (14, 'CALL'),
+    (30, 'LETTER');
+
+-- The step each past-due loan last reached, so a run raises every step once.
+-- The row is removed when the loan is current again.
+CREATE TABLE loan_escalations (
+    loan_id BIGINT PRIMARY KEY REFERENCES loans(id) ON DELETE CASCADE,
+    days_past_due INT NOT NULL,
+    escalated_at TIMESTAMPTZ NOT NULL
+);
+
+-- Loan search looks payments up by reference alone, without the channel the
+-- reference was posted through, which the payment_references key starts
+-- with. Unidentified payments have no reference and are left out of the
+-- index.
+CREATE INDEX IF NOT EXISTS idx_payments_reference ON payments (reference) WHERE reference IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_prepayments_reference ON prepayments (reference) WHERE reference IS NOT NULL;
+
+-- Searches by amount are bounded by a date window; payments are pruned by
+-- partition and read through idx_payments_paid_at, prepayments need their own.
+CREATE INDEX IF NOT EXISTS idx_prepayments_paid_at ON prepayments (paid_at);
+
+-- Customer names are matched on any part, case-insensitively, which a
+-- b-tree on name cannot serve.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE INDEX IF NOT EXISTS idx_customers_name_trgm ON customers USING gin (lower(name) gin_trgm_ops);
+
+-- Runs of the scheduled batch jobs. The async job that carries a run is
+-- deleted after the job retention; its run is kept, with the counts the job
+-- reported and the first item errors, so that past outcomes can be read
+-- without the logs. A run whose job is claimed again after its worker
+-- stopped is closed as failed when the new run starts.
+CREATE TABLE job_runs (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(64) NOT NULL,
+    -- No foreign key: the job row goes away long before its run.
+    job_id UUID NULL,
+    status VARCHAR(16) NOT NULL CHECK (status IN ('RUNNING', 'SUCCEEDED', 'FAILED')),
+    total INTEGER NOT NULL DEFAULT 0,
+    processed INTEGER NOT NULL DEFAULT 0,
+    failed INTEGER NOT NULL DEFAULT 0,
+    error_samples JSONB NOT NULL DEFAULT '[]',
+    error TEXT NULL,
+    started_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+    finished_at TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_job_runs_name_started_at ON job_runs (name, started_at DESC);
+CREATE INDEX IF NOT EXISTS idx_job_runs_running ON job_runs (job_id) WHERE status = 'RUNNING';
+
+-- Locks the runs of a batch job take so that the next run, on any instance,
+-- can tell the previous one is still going. A lock is held until expires_at
+-- and extended by its owner, the async job carrying the run, while it runs;
+-- one whose instance stopped lapses and can be taken by the next owner.
+CREATE TABLE job_locks (
+    name VARCHAR(64) PRIMARY KEY,
+    owner VARCHAR(64) NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+-- The loan's next installment and how many are paid and left, so that reads
+-- of a loan need not load its schedule. A trigger recomputes them from
+-- loan_schedule with every write to it, in the same transaction, including
+-- writes made outside the application. The next installment is the oldest
+-- unpaid one and next_due_amount what is left on it; both are NULL and 0
+-- once the loan is paid off.
+ALTER TABLE loans ADD COLUMN next_due_date DATE NULL;
+ALTER TABLE loans ADD COLUMN next_due_amount DECIMAL(15, 2) NOT NULL DEFAULT 0;
+ALTER TABLE loans ADD COLUMN installments_paid INT NOT NULL DEFAULT 0;
+ALTER TABLE loans ADD COLUMN installments_remaining INT NOT NULL DEFAULT 0;
+
+-- Archiving copies whole rows, so the archive keeps the live table's columns
+-- in the same order.
+ALTER TABLE loans_archive ADD COLUMN next_due_date DATE NULL;
+ALTER TABLE loans_archive ADD COLUMN next_due_amount DECIMAL(15, 2) NOT NULL DEFAULT 0;
+ALTER TABLE loans_archive ADD COLUMN installments_paid INT NOT NULL DEFAULT 0;
+ALTER TABLE loans_archive ADD COLUMN installments_remaining INT NOT NULL DEFAULT 0;
+
+CREATE OR REPLACE FUNCTION refresh_loan_installments(target BIGINT)
+RETURNS VOID AS $$
+BEGIN
+  UPDATE loans l
+  SET next_due_date = n.due_date, next_due_amount = COALESCE(n.due_amount - n.paid_amount, 0),
+      installments_paid = s.paid, installments_remaining = s.remaining
+  FROM (SELECT COUNT(*) FILTER (WHERE status = 'PAID') AS paid,
+               COUNT(*) FILTER (WHERE status != 'PAID') AS remaining
+        FROM loan_schedule WHERE loan_id = target) s
+  LEFT JOIN LATERAL (
+      SELECT due_date, due_amount, paid_amount FROM loan_schedule
+      WHERE loan_id = target AND status != 'PAID'
+      ORDER BY due_date, week_number
+      LIMIT 1) n ON TRUE
+  WHERE l.id = target
+    AND (l.next_due_date, l.next_due_amount, l.installments_paid, l.installments_remaining)
+        IS DISTINCT FROM (n.due_date, COALESCE(n.due_amount - n.paid_amount, 0), s.paid::INT, s.remaining::INT);
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION refresh_loan_installments_on_schedule()
+RETURNS TRIGGER AS $$
+BEGIN
+  IF TG_OP = 'DELETE' THEN
+    PERFORM refresh_loan_installments(OLD.loan_id);
+  ELSE
+    PERFORM refresh_loan_installments(NEW.loan_id);
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- The parent's trigger fires for every partition.
+CREATE TRIGGER refresh_loan_installments
+AFTER INSERT OR UPDATE OR DELETE ON loan_schedule
+FOR EACH ROW
+EXECUTE FUNCTION refresh_loan_installments_on_schedule();
+
+-- Existing loans are filled in without moving their updated_at, which
+-- clients compare with If-Modified-Since.
+ALTER TABLE loans DISABLE TRIGGER set_timestamp_loans;
+SELECT refresh_loan_installments(id) FROM loans;
+ALTER TABLE loans ENABLE TRIGGER set_timestamp_loans;
+
+-- A duplicate customer merged into another keeps its row, deactivated, and
+-- points at the customer that took over its loan and records. Both columns
+-- are set together and stay NULL for customers that were never merged.
+ALTER TABLE customers ADD COLUMN merged_into_id BIGINT NULL REFERENCES customers(id);
+ALTER TABLE customers ADD COLUMN merged_at TIMESTAMPTZ NULL;
+ALTER TABLE customers ADD CONSTRAINT chk_customers_merge_complete
+    CHECK ((merged_into_id IS NULL) = (merged_at IS NULL) AND merged_into_id IS DISTINCT FROM id);
+CREATE INDEX IF NOT EXISTS idx_customers_merged_into_id ON customers (merged_into_id) WHERE merged_into_id IS NOT NULL;
+
+-- Phone numbers and email addresses a customer can be reached at. Only
+-- verified contacts are sent to, and each type has at most one primary.
+CREATE TABLE IF NOT EXISTS customer_contacts (
+    id BIGSERIAL PRIMARY KEY,
+    customer_id BIGINT NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    type VARCHAR(10) NOT NULL,
+    value VARCHAR(254) NOT NULL,
+    verified BOOLEAN NOT NULL DEFAULT FALSE,
+    is_primary BOOLEAN NOT NULL DEFAULT FALSE,
+    verified_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT uq_customer_contacts_value UNIQUE (customer_id, type, value),
+    CONSTRAINT chk_customer_contacts_type CHECK (type IN ('phone', 'email')),
+    CONSTRAINT chk_customer_contacts_primary_verified CHECK (NOT is_primary OR verified)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_customer_contacts_primary ON customer_contacts (customer_id, type) WHERE is_primary;
+
+CREATE TRIGGER set_timestamp_customer_contacts
+BEFORE UPDATE ON customer_contacts
+FOR EACH ROW
+EXECUTE FUNCTION trigger_set_timestamp();
+
+-- The code pending for a contact, kept as a hash. value is what the code was
+-- sent to.
+CREATE TABLE IF NOT EXISTS customer_contact_verifications (
+    contact_id BIGINT PRIMARY KEY REFERENCES customer_contacts(id) ON DELETE CASCADE,
+    value VARCHAR(254) NOT NULL,
+    code_hash CHAR(64) NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    sent_at TIMESTAMPTZ NOT NULL
+);
+
+-- The last number handed to an event about each customer. Events are
+-- numbered from 1 per customer so that consumers can tell when one is
+-- missing or arrived out of order. event_log.sequence stays NULL for events
+-- recorded before they were numbered.
+CREATE TABLE IF NOT EXISTS event_sequences (
+    entity_id BIGINT PRIMARY KEY,
+    last_sequence BIGINT NOT NULL
+);
+
+ALTER TABLE event_log ADD COLUMN IF NOT EXISTS sequence BIGINT NULL;
+
+-- The DPD bucket a delinquent customer was last sent a delinquency notice
+-- for, and when. The nightly delinquency run sends a notice only when the
+-- bucket differs, and clears it once the customer is no longer delinquent,
+-- so a customer hears once per delinquency and once per bucket they move to.
+ALTER TABLE customers ADD COLUMN notified_for_dpd_bucket VARCHAR(16) NULL;
+ALTER TABLE customers ADD COLUMN last_notified_delinquency_at TIMESTAMPTZ NULL;
+
+-- Documents the engine generates for a loan, such as the payoff certificate
+-- issued once the loan is paid off. The content lives in object storage
+-- under storage_key; a loan has at most one document of each type.
+CREATE TABLE IF NOT EXISTS loan_documents (
+    id BIGSERIAL PRIMARY KEY,
+    loan_id BIGINT NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    type VARCHAR(32) NOT NULL CHECK (type IN ('PAYOFF_CERTIFICATE')),
+    file_name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    size_bytes BIGINT NOT NULL CHECK (size_bytes > 0),
+    storage_key TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    CONSTRAINT uq_loan_documents_type UNIQUE (loan_id, type)
+);
+
+CREATE TABLE loan_documents_archive (LIKE loan_documents);
+CREATE INDEX IF NOT EXISTS idx_loan_documents_archive_loan_id ON loan_documents_archive (loan_id);
+
+-- Documents now belong to a loan or a customer and are uploaded by staff as
+-- well as generated: loan contracts and customer ID copies next to payoff
+-- certificates. An upload is PENDING from the moment its upload link is
+-- handed out until the client confirms the file arrived in object storage.
+ALTER TABLE loan_documents RENAME TO documents;
+ALTER TABLE loan_documents_archive RENAME TO documents_archive;
+ALTER INDEX idx_loan_documents_archive_loan_id RENAME TO idx_documents_archive_loan_id;
+
+ALTER TABLE documents ALTER COLUMN loan_id DROP NOT NULL;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS customer_id BIGINT NULL REFERENCES customers(id) ON DELETE CASCADE;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'AVAILABLE';
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS uploaded_by VARCHAR(255) NULL;
+ALTER TABLE documents ADD CONSTRAINT chk_documents_subject CHECK ((loan_id IS NULL) <> (customer_id IS NULL));
+ALTER TABLE documents ADD CONSTRAINT chk_documents_status CHECK (status IN ('PENDING', 'AVAILABLE'));
+ALTER TABLE documents DROP CONSTRAINT IF EXISTS loan_documents_type_check;
+ALTER TABLE documents ADD CONSTRAINT chk_documents_type CHECK (type IN ('CONTRACT', 'ID_COPY', 'PAYOFF_CERTIFICATE'));
+ALTER TABLE documents ADD CONSTRAINT uq_documents_storage_key UNIQUE (storage_key);
+
+-- A loan still gets one payoff certificate, but any number of contracts.
+ALTER TABLE documents DROP CONSTRAINT IF EXISTS uq_loan_documents_type;
+CREATE UNIQUE INDEX IF NOT EXISTS uq_documents_payoff_certificate ON documents (loan_id) WHERE type = 'PAYOFF_CERTIFICATE';
+CREATE INDEX IF NOT EXISTS idx_documents_loan_id ON documents (loan_id) WHERE loan_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_documents_customer_id ON documents (customer_id) WHERE customer_id IS NOT NULL;
+-- The retention run looks documents up by type and age.
+CREATE INDEX IF NOT EXISTS idx_documents_type_created_at ON documents (type, created_at);
+
+-- Archived loans keep their documents; the archive copies rows column for
+-- column, so it gains the same columns in the same order.
+ALTER TABLE documents_archive ALTER COLUMN loan_id DROP NOT NULL;
+ALTER TABLE documents_archive ADD COLUMN IF NOT EXISTS customer_id BIGINT NULL;
+ALTER TABLE documents_archive ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'AVAILABLE';
+ALTER TABLE documents_archive ADD COLUMN IF NOT EXISTS uploaded_by VARCHAR(255) NULL;
+
+-- A loan created in workflow mode is disbursed only after the customer signs
+-- its agreement through the e-signature provider. Each row is one envelope
+-- sent for a loan; a loan has at most one that is still sent or signed, and
+-- a declined or voided one is followed by a new row. The loan awaits
+-- signature under hold_id, released when disbursed_at is set.
+CREATE TABLE IF NOT EXISTS loan_agreements (
+    id BIGSERIAL PRIMARY KEY,
+    loan_id BIGINT NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    document_id BIGINT NULL REFERENCES documents(id) ON DELETE SET NULL,
+    hold_id BIGINT NULL REFERENCES loan_holds(id) ON DELETE SET NULL,
+    provider VARCHAR(32) NOT NULL,
+    envelope_id VARCHAR(255) NOT NULL,
+    status VARCHAR(16) NOT NULL CHECK (status IN ('SENT', 'COMPLETED', 'DECLINED', 'VOIDED')),
+    sent_by VARCHAR(255) NULL,
+    sent_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+    signed_at TIMESTAMPTZ NULL,
+    disbursed_by VARCHAR(255) NULL,
+    disbursed_at TIMESTAMPTZ NULL,
+    CONSTRAINT uq_loan_agreements_envelope UNIQUE (provider, envelope_id),
+    CONSTRAINT chk_loan_agreements_disbursed CHECK (disbursed_at IS NULL OR status = 'COMPLETED')
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_loan_agreements_open ON loan_agreements (loan_id) WHERE status IN ('SENT', 'COMPLETED');
+CREATE INDEX IF NOT EXISTS idx_loan_agreements_loan_id ON loan_agreements (loan_id, sent_at);
+
+CREATE TABLE loan_agreements_archive (LIKE loan_agreements);
+CREATE INDEX IF NOT EXISTS idx_loan_agreements_archive_loan_id ON loan_agreements_archive (loan_id);
+
+-- The shape of the loan's schedule, set by its product when it is booked and
+-- never changed: a rebuild regenerates the schedule from it. Interest-only
+-- loans repay no principal in their first interest_only_weeks installments;
+-- balloon loans leave balloon_amount of principal to the last one.
+ALTER TABLE loans ADD COLUMN schedule_type VARCHAR(16) NOT NULL DEFAULT 'STANDARD'
+    CHECK (schedule_type IN ('STANDARD', 'INTEREST_ONLY', 'BALLOON'));
+ALTER TABLE loans ADD COLUMN interest_only_weeks INT NOT NULL DEFAULT 0 CHECK (interest_only_weeks >= 0);
+ALTER TABLE loans ADD COLUMN balloon_amount DECIMAL(15, 2) NOT NULL DEFAULT 0 CHECK (balloon_amount >= 0);
+ALTER TABLE loans ADD CONSTRAINT chk_loans_schedule_structure CHECK (
+    (schedule_type = 'INTEREST_ONLY' AND interest_only_weeks > 0 AND interest_only_weeks < term_weeks AND balloon_amount = 0)
+    OR (schedule_type = 'BALLOON' AND interest_only_weeks = 0 AND balloon_amount > 0 AND balloon_amount < principal_amount)
+    OR (schedule_type = 'STANDARD' AND interest_only_weeks = 0 AND balloon_amount = 0)
+);
+
+ALTER TABLE loans_archive ADD COLUMN schedule_type VARCHAR(16) NOT NULL DEFAULT 'STANDARD';
+ALTER TABLE loans_archive ADD COLUMN interest_only_weeks INT NOT NULL DEFAULT 0;
+ALTER TABLE loans_archive ADD COLUMN balloon_amount DECIMAL(15, 2) NOT NULL DEFAULT 0;
+
+-- Reports read the archive tables alongside the live ones so that a period
+-- keeps its totals after its loans are archived. These indexes serve their
+-- date ranges.
+CREATE INDEX IF NOT EXISTS idx_payments_archive_paid_at ON payments_archive (paid_at);
+CREATE INDEX IF NOT EXISTS idx_prepayments_archive_paid_at ON prepayments_archive (paid_at);
+CREATE INDEX IF NOT EXISTS idx_tax_lines_archive_created_at ON tax_lines_archive (created_at);
+CREATE INDEX IF NOT EXISTS idx_loan_status_snapshots_archive_date ON loan_status_snapshots_archive (snapshot_date, loan_id);
+
+-- Per-tenant overrides of configured values. A NULL column keeps the
+-- configured value. Until customers and loans carry a tenant, the only
+-- tenant is 'default'.
+CREATE TABLE IF NOT EXISTS tenant_settings (
+    tenant_id VARCHAR(64) PRIMARY KEY,
+    missed_payments INT NULL CHECK (missed_payments >= 1),
+    cure_payments INT NULL CHECK (cure_payments >= 1),
+    rate_limit_rps DOUBLE PRECISION NULL CHECK (rate_limit_rps > 0),
+    rate_limit_burst INT NULL CHECK (rate_limit_burst >= 1),
+    updated_by VARCHAR(255) NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+-- Once every installment is paid, payments settle the fees still owed, each
+-- together with its tax. A ledger entry then names the fee it paid instead
+-- of an installment.
+ALTER TABLE fees ADD COLUMN paid_at TIMESTAMPTZ NULL;
+ALTER TABLE fees_archive ADD COLUMN paid_at TIMESTAMPTZ NULL;
+
+ALTER TABLE payments ALTER COLUMN schedule_id DROP NOT NULL;
+ALTER TABLE payments ADD COLUMN fee_id BIGINT NULL REFERENCES fees(id) ON DELETE CASCADE;
+ALTER TABLE payments ADD CONSTRAINT chk_payments_paid_item CHECK ((schedule_id IS NULL) <> (fee_id IS NULL));
+ALTER TABLE payments_archive ALTER COLUMN schedule_id DROP NOT NULL;
+ALTER TABLE payments_archive ADD COLUMN fee_id BIGINT NULL;
+
+-- Every loan and customer belongs to a tenant, named by the tenant claim of
+-- the token that created it. Rows from before tenants, and rows batch jobs
+-- write, belong to 'default'.
+ALTER TABLE loans ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE loans_archive ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE customers ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+
+CREATE INDEX IF NOT EXISTS idx_loans_tenant_id ON loans (tenant_id, id);
+CREATE INDEX IF NOT EXISTS idx_customers_tenant_id ON customers (tenant_id, id);
+
+-- Row-level security keeps each tenant to its own loans and customers. With
+-- database.tenantEnforcement set to rls the service names the tenant of the
+-- request in app.current_tenant on every connection it uses. A session that
+-- names none, such as a batch job, a migration or psql, sees every row.
+-- FORCE applies the policies to the table owner too, which the service
+-- usually connects as; superusers and BYPASSRLS roles still skip them.
+ALTER TABLE loans ENABLE ROW LEVEL SECURITY;
+ALTER TABLE loans FORCE ROW LEVEL SECURITY;
+CREATE POLICY loans_tenant_isolation ON loans
+    USING (COALESCE(current_setting('app.current_tenant', true), '') IN ('', tenant_id))
+    WITH CHECK (COALESCE(current_setting('app.current_tenant', true), '') IN ('', tenant_id));
+
+ALTER TABLE loans_archive ENABLE ROW LEVEL SECURITY;
+ALTER TABLE loans_archive FORCE ROW LEVEL SECURITY;
+CREATE POLICY loans_archive_tenant_isolation ON loans_archive
+    USING (COALESCE(current_setting('app.current_tenant', true), '') IN ('', tenant_id))
+    WITH CHECK (COALESCE(current_setting('app.current_tenant', true), '') IN ('', tenant_id));
+
+ALTER TABLE customers ENABLE ROW LEVEL SECURITY;
+ALTER TABLE customers FORCE ROW LEVEL SECURITY;
+CREATE POLICY customers_tenant_isolation ON customers
+    USING (COALESCE(current_setting('app.current_tenant', true), '') IN ('', tenant_id))
+    WITH CHECK (COALESCE(current_setting('app.current_tenant', true), '') IN ('', tenant_id));
+
+-- Tenants can override the grace period before an installment counts as
+-- missed, the late fee charged for it and how long a reminder stays worth
+-- sending. The late fee is a fee type of its own.
+ALTER TABLE tenant_settings ADD COLUMN grace_days INT NULL CHECK (grace_days >= 0);
+ALTER TABLE tenant_settings ADD COLUMN late_fee DECIMAL(15, 2) NULL CHECK (late_fee >= 0);
+ALTER TABLE tenant_settings ADD COLUMN reminder_window_days INT NULL CHECK (reminder_window_days >= 0);
+
+ALTER TABLE fees DROP CONSTRAINT IF EXISTS fees_fee_type_check;
+ALTER TABLE fees ADD CONSTRAINT fees_fee_type_check CHECK (fee_type IN ('PROCESSING', 'BOUNCE', 'LEGAL', 'LATE'));
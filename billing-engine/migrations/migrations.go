@@ -0,0 +1,119 @@
+// Package migrations holds the numbered schema migrations, embedded so the
+// binary can tell whether a database is up to date. Nothing here applies
+// them; deployments run the up sections, or init.sql, themselves.
+package migrations
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	markerUp   = "-- +migrate Up"
+	markerDown = "-- +migrate Down"
+)
+
+//go:embed [0-9]*.sql
+var files embed.FS
+
+// Migration is the up section of one numbered file.
+type Migration struct {
+	Name string
+	Up   string
+}
+
+// Object is a table or index a migration creates, or with Column set a
+// column it adds to the table Relation, or with Policy set a row-level
+// security policy it creates on Relation.
+type Object struct {
+	Relation string
+	Column   string
+	Policy   string
+}
+
+func (o Object) String() string {
+	switch {
+	case o.Column != "":
+		return o.Relation + "." + o.Column
+	case o.Policy != "":
+		return "policy " + o.Policy + " on " + o.Relation
+	}
+	return o.Relation
+}
+
+// Embedded returns the migrations built into the binary.
+func Embedded() ([]Migration, error) {
+	return Load(files)
+}
+
+// Load reads the numbered migrations in fsys in file name order. init.sql,
+// the combined copy docker compose mounts, is skipped.
+func Load(fsys fs.FS) ([]Migration, error) {
+	paths, err := fs.Glob(fsys, "[0-9]*.sql")
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, errors.New("no migrations found")
+	}
+	sort.Strings(paths)
+
+	migrations := make([]Migration, 0, len(paths))
+	for _, path := range paths {
+		content, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return nil, err
+		}
+		up, err := UpSection(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		migrations = append(migrations, Migration{Name: path, Up: up})
+	}
+	return migrations, nil
+}
+
+// UpSection returns the statements between the Up and Down markers.
+func UpSection(content string) (string, error) {
+	start := strings.Index(content, markerUp)
+	if start < 0 {
+		return "", fmt.Errorf("missing %q marker", markerUp)
+	}
+	up := content[start+len(markerUp):]
+	if end := strings.Index(up, markerDown); end >= 0 {
+		up = up[:end]
+	}
+	up = strings.TrimSpace(up)
+	if up == "" {
+		return "", fmt.Errorf("empty up section")
+	}
+	return up, nil
+}
+
+var (
+	createRelation = regexp.MustCompile(`(?i)\bCREATE\s+(?:TABLE|(?:UNIQUE\s+)?INDEX(?:\s+CONCURRENTLY)?)\s+(?:IF\s+NOT\s+EXISTS\s+)?([a-z_][a-z0-9_]*)[\s(;]`)
+	addColumn      = regexp.MustCompile(`(?i)\bALTER\s+TABLE\s+(?:IF\s+EXISTS\s+)?([a-z_][a-z0-9_]*)\s+ADD\s+COLUMN\s+(?:IF\s+NOT\s+EXISTS\s+)?([a-z_][a-z0-9_]*)\s`)
+	createPolicy   = regexp.MustCompile(`(?i)\bCREATE\s+POLICY\s+([a-z_][a-z0-9_]*)\s+ON\s+([a-z_][a-z0-9_]*)\s`)
+)
+
+// Objects lists the tables, indexes, columns and policies m adds. Objects created by
+// dynamic SQL, such as the partitions migration 020 formats, and functions,
+// triggers and constraints are not listed.
+func (m Migration) Objects() []Object {
+	var objects []Object
+	for _, match := range createRelation.FindAllStringSubmatch(m.Up, -1) {
+		objects = append(objects, Object{Relation: strings.ToLower(match[1])})
+	}
+	for _, match := range addColumn.FindAllStringSubmatch(m.Up, -1) {
+		objects = append(objects, Object{Relation: strings.ToLower(match[1]), Column: strings.ToLower(match[2])})
+	}
+	for _, match := range createPolicy.FindAllStringSubmatch(m.Up, -1) {
+		objects = append(objects, Object{Relation: strings.ToLower(match[2]), Policy: strings.ToLower(match[1])})
+	}
+	return objects
+}
@@ -0,0 +1,67 @@
+package migrations
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpSection(t *testing.T) {
+	up, err := UpSection("-- +migrate Up\nCREATE TABLE a (id INT);\n\n-- +migrate Down\nDROP TABLE a;\n")
+	require.NoError(t, err)
+	assert.Equal(t, "CREATE TABLE a (id INT);", up)
+
+	up, err = UpSection("-- +migrate Up\nALTER TABLE a ADD COLUMN b INT;\n")
+	require.NoError(t, err)
+	assert.Equal(t, "ALTER TABLE a ADD COLUMN b INT;", up)
+
+	_, err = UpSection("CREATE TABLE a (id INT);")
+	assert.ErrorContains(t, err, "missing")
+
+	_, err = UpSection("-- +migrate Up\n-- +migrate Down\nDROP TABLE a;")
+	assert.ErrorContains(t, err, "empty")
+}
+
+func TestEmbedded(t *testing.T) {
+	embedded, err := Embedded()
+	require.NoError(t, err)
+	require.NotEmpty(t, embedded)
+	assert.Equal(t, "001_create_loans_table.sql", embedded[0].Name)
+
+	_, err = Load(fstest.MapFS{"init.sql": {Data: []byte("SELECT 1;")}})
+	assert.ErrorContains(t, err, "no migrations")
+}
+
+func TestObjects(t *testing.T) {
+	m := Migration{Name: "040_example.sql", Up: `
+CREATE TABLE IF NOT EXISTS widgets (id BIGSERIAL PRIMARY KEY);
+CREATE UNIQUE INDEX uq_widgets_name ON widgets (name);
+CREATE TABLE widgets_archive (LIKE widgets);
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS widget_id BIGINT NULL;
+ALTER TABLE customers ADD CONSTRAINT fk_customers_widget FOREIGN KEY (widget_id) REFERENCES widgets (id);
+CREATE OR REPLACE FUNCTION touch_widget() RETURNS TRIGGER AS $$ BEGIN RETURN NEW; END; $$ LANGUAGE plpgsql;
+DO $$ BEGIN EXECUTE format('CREATE TABLE widgets_p%s PARTITION OF widgets', 1); END $$;
+ALTER TABLE widgets ENABLE ROW LEVEL SECURITY;
+CREATE POLICY widgets_tenant ON widgets USING (true);`}
+
+	assert.Equal(t, []Object{
+		{Relation: "widgets"}, {Relation: "uq_widgets_name"}, {Relation: "widgets_archive"},
+		{Relation: "customers", Column: "widget_id"}, {Relation: "widgets", Policy: "widgets_tenant"},
+	}, m.Objects())
+	assert.Equal(t, "customers.widget_id", m.Objects()[3].String())
+	assert.Equal(t, "policy widgets_tenant on widgets", m.Objects()[4].String())
+}
+
+// Every migration from the one that added job runs on creates something the
+// doctor command can look for.
+func TestEmbeddedObjects(t *testing.T) {
+	embedded, err := Embedded()
+	require.NoError(t, err)
+	for _, m := range embedded {
+		if m.Name >= "031" {
+			assert.NotEmpty(t, m.Objects(), m.Name)
+		}
+	}
+}
@@ -0,0 +1,2007 @@
+// Code generated by cmd/openapi from the OpenAPI document. DO NOT EDIT.
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+type AdjustmentPlanResponse struct {
+	Adjustment      ScheduleAdjustmentResponse `json:"adjustment"`
+	Changes         []ScheduleChangeResponse   `json:"changes"`
+	LoanID          string                     `json:"loanId"`
+	TotalLoanAmount string                     `json:"totalLoanAmount"`
+}
+
+type AdvanceClockRequest struct {
+	Days int `json:"days"`
+}
+
+type AgreementResponse struct {
+	DisbursedAt *time.Time `json:"disbursedAt,omitempty"`
+	DisbursedBy string     `json:"disbursedBy,omitempty"`
+	DocumentID  string     `json:"documentId,omitempty"`
+	EnvelopeID  string     `json:"envelopeId"`
+	ID          string     `json:"id"`
+	LoanID      string     `json:"loanId"`
+	Provider    string     `json:"provider"`
+	SentAt      time.Time  `json:"sentAt"`
+	SentBy      string     `json:"sentBy,omitempty"`
+	SignedAt    *time.Time `json:"signedAt,omitempty"`
+	Status      string     `json:"status"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+}
+
+type ApplyAdjustmentRequest struct {
+	Kind     string `json:"kind"`
+	Reason   string `json:"reason"`
+	StartsOn string `json:"startsOn"`
+	Weeks    int    `json:"weeks"`
+}
+
+type AssignLoanRequest struct {
+	LoanID int64 `json:"loanId"`
+}
+
+type AttachmentResponse struct {
+	ContentType string    `json:"contentType"`
+	CreatedAt   time.Time `json:"createdAt"`
+	FileName    string    `json:"fileName"`
+	ID          string    `json:"id"`
+	SizeBytes   int64     `json:"sizeBytes"`
+	SubjectID   string    `json:"subjectId"`
+	SubjectType string    `json:"subjectType"`
+	UploadedBy  string    `json:"uploadedBy,omitempty"`
+}
+
+type BalanceItemResponse struct {
+	Amount      string `json:"amount"`
+	Description string `json:"description,omitempty"`
+	Kind        string `json:"kind"`
+}
+
+type ChannelCollectionsResponse struct {
+	Amount   string `json:"amount"`
+	Channel  string `json:"channel"`
+	Payments int    `json:"payments"`
+}
+
+type CollectionActionResponse struct {
+	AssignmentID   string    `json:"assignmentId"`
+	Collector      string    `json:"collector,omitempty"`
+	CreatedAt      time.Time `json:"createdAt"`
+	ID             string    `json:"id"`
+	LoanID         string    `json:"loanId"`
+	Note           string    `json:"note,omitempty"`
+	PromisedAmount *string   `json:"promisedAmount,omitempty"`
+	PromisedDate   *string   `json:"promisedDate,omitempty"`
+	Type           string    `json:"type"`
+}
+
+type CollectionAssignmentResponse struct {
+	AssignedAt   time.Time  `json:"assignedAt"`
+	Bucket       string     `json:"bucket"`
+	Collector    string     `json:"collector"`
+	CustomerID   *string    `json:"customerId,omitempty"`
+	CustomerName string     `json:"customerName,omitempty"`
+	DaysPastDue  int        `json:"daysPastDue"`
+	ID           string     `json:"id"`
+	LastActionAt *time.Time `json:"lastActionAt,omitempty"`
+	LoanID       string     `json:"loanId"`
+	LoanStatus   string     `json:"loanStatus,omitempty"`
+	Outstanding  string     `json:"outstanding"`
+	RuleID       *string    `json:"ruleId,omitempty"`
+	Status       string     `json:"status"`
+}
+
+type CollectionRuleResponse struct {
+	Active         bool      `json:"active"`
+	Bucket         string    `json:"bucket,omitempty"`
+	Collector      string    `json:"collector"`
+	CreatedAt      time.Time `json:"createdAt"`
+	ID             string    `json:"id"`
+	MaxOutstanding *string   `json:"maxOutstanding,omitempty"`
+	MinOutstanding *string   `json:"minOutstanding,omitempty"`
+	Name           string    `json:"name"`
+	Priority       int       `json:"priority"`
+	Region         string    `json:"region,omitempty"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+type CollectionsByChannelResponse struct {
+	Amount    string                       `json:"amount"`
+	ByChannel []ChannelCollectionsResponse `json:"byChannel"`
+	From      string                       `json:"from"`
+	Payments  int                          `json:"payments"`
+	To        string                       `json:"to"`
+}
+
+type ConfirmContactRequest struct {
+	Code string `json:"code"`
+}
+
+type ContactResponse struct {
+	CreatedAt  time.Time  `json:"createdAt"`
+	CustomerID string     `json:"customerId"`
+	ID         string     `json:"id"`
+	Primary    bool       `json:"primary"`
+	Type       string     `json:"type"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+	Value      string     `json:"value"`
+	Verified   bool       `json:"verified"`
+	VerifiedAt *time.Time `json:"verifiedAt,omitempty"`
+}
+
+type ContactVerificationResponse struct {
+	ContactID string    `json:"contactId"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type CreateCollectionRuleRequest struct {
+	Bucket         string  `json:"bucket,omitempty"`
+	Collector      string  `json:"collector"`
+	MaxOutstanding *string `json:"maxOutstanding,omitempty"`
+	MinOutstanding *string `json:"minOutstanding,omitempty"`
+	Name           string  `json:"name"`
+	Priority       int     `json:"priority"`
+	Region         string  `json:"region,omitempty"`
+}
+
+type CreateContactRequest struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type CreateCustomerRequest struct {
+	Address     string `json:"address"`
+	ExternalRef string `json:"externalRef,omitempty"`
+	Force       bool   `json:"force,omitempty"`
+	Name        string `json:"name"`
+	PublicID    string `json:"publicId,omitempty"`
+}
+
+type CreateDocumentRequest struct {
+	ContentType string `json:"contentType"`
+	FileName    string `json:"fileName"`
+	SizeBytes   int64  `json:"sizeBytes"`
+	Type        string `json:"type"`
+}
+
+type CreateLoanRequest struct {
+	AnnualInterestRate float64 `json:"annualInterestRate"`
+	CustomerID         int64   `json:"customerId"`
+	ExternalRef        string  `json:"externalRef,omitempty"`
+	Principal          float64 `json:"principal"`
+	Product            string  `json:"product,omitempty"`
+	PublicID           string  `json:"publicId,omitempty"`
+	StartDate          string  `json:"startDate"`
+	TermWeeks          int     `json:"termWeeks"`
+}
+
+type CreateMandateRequest struct {
+	AccountHolder string `json:"accountHolder"`
+	AccountNumber string `json:"accountNumber"`
+	BankCode      string `json:"bankCode"`
+	Reference     string `json:"reference,omitempty"`
+	SignedAt      string `json:"signedAt"`
+}
+
+type CreateNoteRequest struct {
+	Body string `json:"body"`
+}
+
+type CustomerImportResponse struct {
+	Created    int                       `json:"created"`
+	Duplicates int                       `json:"duplicates"`
+	Failed     int                       `json:"failed"`
+	Invalid    int                       `json:"invalid"`
+	Results    []CustomerImportRowResult `json:"results"`
+	Total      int                       `json:"total"`
+}
+
+type CustomerImportRowResult struct {
+	CustomerID  *string `json:"customerId,omitempty"`
+	Error       string  `json:"error,omitempty"`
+	ExternalRef string  `json:"externalRef,omitempty"`
+	Line        int     `json:"line"`
+	Status      string  `json:"status"`
+}
+
+type CustomerMergeResponse struct {
+	LoanID *string               `json:"loanId,omitempty"`
+	Moved  MergedRecordsResponse `json:"moved"`
+	Source CustomerResponse      `json:"source"`
+	Target CustomerResponse      `json:"target"`
+}
+
+type CustomerOverviewResponse struct {
+	Collections            OverviewCollectionsResponse `json:"collections,omitempty"`
+	Customer               CustomerResponse            `json:"customer"`
+	Loans                  []OverviewLoanResponse      `json:"loans"`
+	NotificationsAvailable bool                        `json:"notificationsAvailable"`
+	RecentNotifications    []NotificationResponse      `json:"recentNotifications"`
+	RecentPayments         []PaymentResponse           `json:"recentPayments"`
+}
+
+type CustomerResponse struct {
+	Active       bool       `json:"active"`
+	Address      string     `json:"address"`
+	CreateDate   time.Time  `json:"createDate"`
+	CustomerID   string     `json:"customerId"`
+	ExternalRef  *string    `json:"externalRef,omitempty"`
+	IsDelinquent bool       `json:"isDelinquent"`
+	LoanID       *string    `json:"loanId,omitempty"`
+	MergedAt     *time.Time `json:"mergedAt,omitempty"`
+	MergedIntoID *string    `json:"mergedIntoId,omitempty"`
+	Name         string     `json:"name"`
+	PublicID     string     `json:"publicId,omitempty"`
+	RiskGrade    *string    `json:"riskGrade,omitempty"`
+	RiskScore    *int       `json:"riskScore,omitempty"`
+	RiskScoredAt *time.Time `json:"riskScoredAt,omitempty"`
+	UpdatedAt    time.Time  `json:"updatedAt"`
+}
+
+type CustomerSummaryLoanResponse struct {
+	Bucket            string  `json:"bucket"`
+	DaysPastDue       int     `json:"daysPastDue"`
+	InstallmentsPaid  int     `json:"installmentsPaid"`
+	InstallmentsTotal int     `json:"installmentsTotal"`
+	LoanID            string  `json:"loanId"`
+	NextDueAmount     string  `json:"nextDueAmount"`
+	NextDueDate       *string `json:"nextDueDate,omitempty"`
+	Outstanding       string  `json:"outstanding"`
+	PrincipalAmount   string  `json:"principalAmount"`
+	PublicID          string  `json:"publicId,omitempty"`
+	Status            string  `json:"status"`
+	TotalLoanAmount   string  `json:"totalLoanAmount"`
+	TotalPaid         string  `json:"totalPaid"`
+}
+
+type CustomerSummaryResponse struct {
+	Active       bool                        `json:"active"`
+	Address      string                      `json:"address"`
+	CustomerID   string                      `json:"customerId"`
+	IsDelinquent bool                        `json:"isDelinquent"`
+	Loan         CustomerSummaryLoanResponse `json:"loan,omitempty"`
+	Name         string                      `json:"name"`
+	PublicID     string                      `json:"publicId,omitempty"`
+	RefreshedAt  time.Time                   `json:"refreshedAt"`
+}
+
+type DelinquentResponse struct {
+	IsDelinquent bool   `json:"isDelinquent"`
+	LoanID       string `json:"loanId"`
+}
+
+type DeprecatedUsageResponse struct {
+	Client    string    `json:"client"`
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+	Method    string    `json:"method"`
+	Requests  int64     `json:"requests"`
+	Route     string    `json:"route"`
+}
+
+type DirectDebitResultOutcome struct {
+	EndToEndID string  `json:"endToEndId"`
+	Error      string  `json:"error,omitempty"`
+	Line       int     `json:"line"`
+	LoanID     *string `json:"loanId,omitempty"`
+	Status     string  `json:"status"`
+}
+
+type DirectDebitResultsResponse struct {
+	Collected int                        `json:"collected"`
+	Failed    int                        `json:"failed"`
+	Results   []DirectDebitResultOutcome `json:"results"`
+	Skipped   int                        `json:"skipped"`
+	Total     int                        `json:"total"`
+	Unapplied int                        `json:"unapplied"`
+}
+
+type DocumentResponse struct {
+	ContentType string    `json:"contentType"`
+	CreatedAt   time.Time `json:"createdAt"`
+	FileName    string    `json:"fileName"`
+	ID          string    `json:"id"`
+	SizeBytes   int64     `json:"sizeBytes"`
+	Status      string    `json:"status"`
+	SubjectID   string    `json:"subjectId"`
+	SubjectType string    `json:"subjectType"`
+	Type        string    `json:"type"`
+	UploadedBy  string    `json:"uploadedBy,omitempty"`
+}
+
+type DocumentUploadResponse struct {
+	Document        DocumentResponse `json:"document"`
+	UploadExpiresAt time.Time        `json:"uploadExpiresAt"`
+	UploadUrl       string           `json:"uploadUrl"`
+}
+
+type ErrorDetail struct {
+	Code           string            `json:"code,omitempty"`
+	Details        map[string]string `json:"details,omitempty"`
+	ExpectedAmount string            `json:"expectedAmount,omitempty"`
+	Field          string            `json:"field,omitempty"`
+	Message        string            `json:"message"`
+	Retryable      bool              `json:"retryable,omitempty"`
+}
+
+type ErrorResponse struct {
+	Error ErrorDetail `json:"error"`
+}
+
+type EscalationStepRequest struct {
+	Action      string `json:"action"`
+	DaysPastDue int    `json:"daysPastDue"`
+}
+
+type EscalationStepResponse struct {
+	Action      string    `json:"action"`
+	CreatedAt   time.Time `json:"createdAt"`
+	DaysPastDue int       `json:"daysPastDue"`
+	Notifies    bool      `json:"notifies"`
+}
+
+type EventRecordResponse struct {
+	EntityID       string     `json:"entityId"`
+	EventID        string     `json:"eventId"`
+	ID             string     `json:"id"`
+	LastReplayedAt *time.Time `json:"lastReplayedAt,omitempty"`
+	OccurredAt     time.Time  `json:"occurredAt"`
+	Payload        any        `json:"payload"`
+	PublishedAt    *time.Time `json:"publishedAt,omitempty"`
+	ReplayCount    int        `json:"replayCount"`
+	Sequence       int64      `json:"sequence,omitempty"`
+	Type           string     `json:"type"`
+}
+
+type FeeResponse struct {
+	Amount   string              `json:"amount"`
+	ID       string              `json:"id"`
+	LoanID   string              `json:"loanId"`
+	PaidAt   *time.Time          `json:"paidAt,omitempty"`
+	PostedAt time.Time           `json:"postedAt"`
+	PostedBy *string             `json:"postedBy,omitempty"`
+	Reason   string              `json:"reason"`
+	Tax      TaxLineResponse     `json:"tax,omitempty"`
+	Type     string              `json:"type"`
+	Waived   bool                `json:"waived"`
+	Waivers  []FeeWaiverResponse `json:"waivers"`
+}
+
+type FeeTypeResponse struct {
+	Description string `json:"description"`
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+}
+
+type FeeWaiverRequest struct {
+	Reason string `json:"reason"`
+}
+
+type FeeWaiverResponse struct {
+	DecidedAt   *time.Time `json:"decidedAt,omitempty"`
+	DecidedBy   *string    `json:"decidedBy,omitempty"`
+	FeeID       string     `json:"feeId"`
+	ID          string     `json:"id"`
+	Reason      string     `json:"reason"`
+	RequestedAt time.Time  `json:"requestedAt"`
+	RequestedBy *string    `json:"requestedBy,omitempty"`
+	Status      string     `json:"status"`
+}
+
+type GraphQLError struct {
+	Message string `json:"message"`
+	Path    []any  `json:"path,omitempty"`
+}
+
+type GraphQLRequest struct {
+	OperationName string         `json:"operationName,omitempty"`
+	Query         string         `json:"query"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+type GraphQLResponse struct {
+	Data   any            `json:"data"`
+	Errors []GraphQLError `json:"errors,omitempty"`
+}
+
+type IntegrityFindingResponse struct {
+	Check       string    `json:"check"`
+	Detail      string    `json:"detail"`
+	EntityID    string    `json:"entityId"`
+	EntityType  string    `json:"entityType"`
+	FirstSeenAt time.Time `json:"firstSeenAt"`
+	ID          string    `json:"id"`
+	LastSeenAt  time.Time `json:"lastSeenAt"`
+}
+
+type JobResponse struct {
+	Attempts   int        `json:"attempts"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	Error      string     `json:"error,omitempty"`
+	FinishedAt *time.Time `json:"finishedAt"`
+	ID         string     `json:"id"`
+	Kind       string     `json:"kind"`
+	Processed  int        `json:"processed"`
+	Result     any        `json:"result,omitempty"`
+	StartedAt  *time.Time `json:"startedAt"`
+	Status     string     `json:"status"`
+	Total      int        `json:"total"`
+}
+
+type JobRunResponse struct {
+	DurationSeconds float64    `json:"durationSeconds"`
+	Error           string     `json:"error,omitempty"`
+	ErrorSamples    []string   `json:"errorSamples"`
+	Failed          int        `json:"failed"`
+	FinishedAt      *time.Time `json:"finishedAt"`
+	ID              string     `json:"id"`
+	JobID           string     `json:"jobId,omitempty"`
+	Name            string     `json:"name"`
+	Processed       int        `json:"processed"`
+	StartedAt       time.Time  `json:"startedAt"`
+	Status          string     `json:"status"`
+	Total           int        `json:"total"`
+	UpdatedAt       time.Time  `json:"updatedAt"`
+}
+
+type LoanHistoryResponse struct {
+	From      string                 `json:"from"`
+	LoanID    string                 `json:"loanId"`
+	Snapshots []LoanSnapshotResponse `json:"snapshots"`
+	To        string                 `json:"to"`
+}
+
+type LoanHoldResponse struct {
+	ID         string     `json:"id"`
+	LoanID     string     `json:"loanId"`
+	PlacedAt   time.Time  `json:"placedAt"`
+	PlacedBy   *string    `json:"placedBy,omitempty"`
+	Reason     string     `json:"reason"`
+	ReleasedAt *time.Time `json:"releasedAt,omitempty"`
+	ReleasedBy *string    `json:"releasedBy,omitempty"`
+}
+
+type LoanResponse struct {
+	Adjustments           []ScheduleAdjustmentResponse `json:"adjustments,omitempty"`
+	BalloonAmount         string                       `json:"balloonAmount,omitempty"`
+	CreatedAt             time.Time                    `json:"createdAt"`
+	DaysPastDue           int                          `json:"daysPastDue"`
+	ExternalRef           *string                      `json:"externalRef,omitempty"`
+	Hold                  LoanHoldResponse             `json:"hold,omitempty"`
+	ID                    string                       `json:"id"`
+	InstallmentsPaid      int                          `json:"installmentsPaid"`
+	InstallmentsRemaining int                          `json:"installmentsRemaining"`
+	InterestOnlyWeeks     int                          `json:"interestOnlyWeeks,omitempty"`
+	InterestRate          string                       `json:"interestRate"`
+	NextDueAmount         string                       `json:"nextDueAmount,omitempty"`
+	NextDueDate           string                       `json:"nextDueDate,omitempty"`
+	Prepayments           []PrepaymentResponse         `json:"prepayments,omitempty"`
+	PrincipalAmount       string                       `json:"principalAmount"`
+	PublicID              string                       `json:"publicId,omitempty"`
+	RateHistory           []RateChangeResponse         `json:"rateHistory,omitempty"`
+	Schedule              []ScheduleEntryResponse      `json:"schedule,omitempty"`
+	ScheduleType          string                       `json:"scheduleType"`
+	StartDate             string                       `json:"startDate"`
+	Status                string                       `json:"status"`
+	TermWeeks             int                          `json:"termWeeks"`
+	TotalLoanAmount       string                       `json:"totalLoanAmount"`
+	UpdatedAt             time.Time                    `json:"updatedAt"`
+	WeeklyPaymentAmount   string                       `json:"weeklyPaymentAmount"`
+}
+
+type LoanSearchMatchResponse struct {
+	Bucket       string                `json:"bucket"`
+	CustomerID   *string               `json:"customerId,omitempty"`
+	CustomerName *string               `json:"customerName,omitempty"`
+	DaysPastDue  int                   `json:"daysPastDue"`
+	LoanID       string                `json:"loanId"`
+	LoanPublicID string                `json:"loanPublicId,omitempty"`
+	Payment      SearchPaymentResponse `json:"payment,omitempty"`
+	Status       string                `json:"status"`
+}
+
+type LoanSnapshotResponse struct {
+	Date        string `json:"date"`
+	Dpd         int    `json:"dpd"`
+	Outstanding string `json:"outstanding"`
+	Status      string `json:"status"`
+}
+
+type LoanTermsResponse struct {
+	EndDate             string `json:"endDate"`
+	InterestRate        string `json:"interestRate"`
+	TermWeeks           int    `json:"termWeeks"`
+	TotalInterest       string `json:"totalInterest"`
+	TotalLoanAmount     string `json:"totalLoanAmount"`
+	WeeklyPaymentAmount string `json:"weeklyPaymentAmount"`
+}
+
+type MakePaymentRequest struct {
+	Amount      string `json:"amount"`
+	Channel     string `json:"channel,omitempty"`
+	CollectorID string `json:"collectorId,omitempty"`
+	Reference   string `json:"reference,omitempty"`
+}
+
+type MandateResponse struct {
+	AccountHolder string    `json:"accountHolder"`
+	AccountNumber string    `json:"accountNumber"`
+	BankCode      string    `json:"bankCode"`
+	CreatedAt     time.Time `json:"createdAt"`
+	CustomerID    string    `json:"customerId"`
+	ID            string    `json:"id"`
+	Reference     string    `json:"reference"`
+	SignedAt      string    `json:"signedAt"`
+	Status        string    `json:"status"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+type MergeCustomerRequest struct {
+	SourceID int64 `json:"sourceId"`
+}
+
+type MergedRecordsResponse struct {
+	ArchivedLoans         int64 `json:"archivedLoans"`
+	Attachments           int64 `json:"attachments"`
+	CollectionAssignments int64 `json:"collectionAssignments"`
+	Documents             int64 `json:"documents"`
+	Events                int64 `json:"events"`
+	Mandates              int64 `json:"mandates"`
+	Notes                 int64 `json:"notes"`
+}
+
+type NoteResponse struct {
+	Author      string    `json:"author,omitempty"`
+	Body        string    `json:"body"`
+	CreatedAt   time.Time `json:"createdAt"`
+	ID          string    `json:"id"`
+	SubjectID   string    `json:"subjectId"`
+	SubjectType string    `json:"subjectType"`
+}
+
+type NotificationResponse struct {
+	Channel   string     `json:"channel"`
+	CreatedAt time.Time  `json:"createdAt"`
+	Error     string     `json:"error,omitempty"`
+	Event     string     `json:"event"`
+	ID        string     `json:"id"`
+	Recipient string     `json:"recipient"`
+	SentAt    *time.Time `json:"sentAt,omitempty"`
+	Status    string     `json:"status"`
+	Subject   string     `json:"subject,omitempty"`
+}
+
+type OutstandingBreakdownResponse struct {
+	AccruedInterest string                `json:"accruedInterest"`
+	AsOf            time.Time             `json:"asOf"`
+	Credits         string                `json:"credits"`
+	Fees            string                `json:"fees"`
+	Installments    string                `json:"installments"`
+	Interest        string                `json:"interest"`
+	Items           []BalanceItemResponse `json:"items"`
+	PastDue         string                `json:"pastDue"`
+	Penalties       string                `json:"penalties"`
+	Principal       string                `json:"principal"`
+	Suspense        string                `json:"suspense"`
+	Tax             string                `json:"tax"`
+}
+
+type OutstandingResponse struct {
+	Breakdown         OutstandingBreakdownResponse `json:"breakdown,omitempty"`
+	LoanID            string                       `json:"loanId"`
+	OutstandingAmount string                       `json:"outstandingAmount"`
+}
+
+type OverviewCollectionsResponse struct {
+	Actions    []CollectionActionResponse   `json:"actions"`
+	Assignment CollectionAssignmentResponse `json:"assignment"`
+}
+
+type OverviewLoanResponse struct {
+	Bucket      string       `json:"bucket"`
+	Loan        LoanResponse `json:"loan"`
+	Outstanding string       `json:"outstanding"`
+}
+
+type PaymentResponse struct {
+	Amount      string    `json:"amount"`
+	Channel     string    `json:"channel"`
+	CollectorID *string   `json:"collectorId,omitempty"`
+	FeeID       *string   `json:"feeId,omitempty"`
+	ID          string    `json:"id"`
+	LoanID      string    `json:"loanId"`
+	PaidAt      time.Time `json:"paidAt"`
+	Reference   *string   `json:"reference,omitempty"`
+	ScheduleID  *string   `json:"scheduleId,omitempty"`
+}
+
+type PlaceHoldRequest struct {
+	Reason string `json:"reason"`
+}
+
+type PortfolioBucketResponse struct {
+	Loans       int    `json:"loans"`
+	Name        string `json:"name"`
+	Outstanding string `json:"outstanding"`
+}
+
+type PortfolioLoanResponse struct {
+	Date        string `json:"date"`
+	Dpd         int    `json:"dpd"`
+	LoanID      string `json:"loanId"`
+	Outstanding string `json:"outstanding"`
+	Status      string `json:"status"`
+}
+
+type PortfolioResponse struct {
+	AsOf        string                    `json:"asOf"`
+	ByDpd       []PortfolioBucketResponse `json:"byDpd"`
+	ByStatus    []PortfolioBucketResponse `json:"byStatus"`
+	Date        string                    `json:"date"`
+	Loans       int                       `json:"loans"`
+	Outstanding string                    `json:"outstanding"`
+}
+
+type PostFeeRequest struct {
+	Amount string `json:"amount"`
+	Reason string `json:"reason"`
+	Type   string `json:"type"`
+}
+
+type PreferencesResponse struct {
+	CustomerID          string     `json:"customerId"`
+	Language            string     `json:"language,omitempty"`
+	MarketingOptOut     bool       `json:"marketingOptOut"`
+	PreferredChannel    string     `json:"preferredChannel,omitempty"`
+	TransactionalOptOut bool       `json:"transactionalOptOut"`
+	UpdatedAt           *time.Time `json:"updatedAt,omitempty"`
+}
+
+type PrepaymentRequest struct {
+	Amount    string `json:"amount"`
+	Channel   string `json:"channel,omitempty"`
+	Option    string `json:"option"`
+	Reference string `json:"reference,omitempty"`
+}
+
+type PrepaymentResponse struct {
+	AfterWeek                   int       `json:"afterWeek"`
+	Amount                      string    `json:"amount"`
+	Channel                     string    `json:"channel"`
+	ID                          string    `json:"id"`
+	Option                      string    `json:"option"`
+	PaidAt                      time.Time `json:"paidAt"`
+	PreviousTermWeeks           int       `json:"previousTermWeeks"`
+	PreviousTotalLoanAmount     string    `json:"previousTotalLoanAmount"`
+	PreviousWeeklyPaymentAmount string    `json:"previousWeeklyPaymentAmount"`
+	Principal                   string    `json:"principal"`
+	RecordedBy                  *string   `json:"recordedBy,omitempty"`
+	Reference                   *string   `json:"reference,omitempty"`
+	TermWeeks                   int       `json:"termWeeks"`
+	TotalLoanAmount             string    `json:"totalLoanAmount"`
+	WeeklyPaymentAmount         string    `json:"weeklyPaymentAmount"`
+}
+
+type RateChangeResponse struct {
+	ChangedBy     *string   `json:"changedBy,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+	EffectiveFrom string    `json:"effectiveFrom"`
+	ID            string    `json:"id"`
+	InterestRate  string    `json:"interestRate"`
+	PreviousRate  string    `json:"previousRate"`
+}
+
+type RateLimitConsumerResponse struct {
+	Allowed   int64     `json:"allowed"`
+	LastSeen  time.Time `json:"lastSeen"`
+	Limited   int64     `json:"limited"`
+	Principal string    `json:"principal"`
+	Requests  int64     `json:"requests"`
+}
+
+type RateLimitListsResponse struct {
+	Allowlist []string `json:"allowlist"`
+	Blocklist []string `json:"blocklist"`
+}
+
+type ReamortizationResponse struct {
+	Changes    []ScheduleChangeResponse `json:"changes"`
+	LoanID     string                   `json:"loanId"`
+	Prepayment PrepaymentResponse       `json:"prepayment"`
+}
+
+type ReassignCollectionRequest struct {
+	Collector string `json:"collector"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+type RecordCollectionActionRequest struct {
+	Note           string  `json:"note,omitempty"`
+	PromisedAmount *string `json:"promisedAmount,omitempty"`
+	PromisedDate   *string `json:"promisedDate,omitempty"`
+	Type           string  `json:"type"`
+}
+
+type ReplaceEscalationStepsRequest struct {
+	Steps []EscalationStepRequest `json:"steps"`
+}
+
+type ReplayEventsRequest struct {
+	EntityID        *int64     `json:"entityId,omitempty"`
+	Limit           int        `json:"limit,omitempty"`
+	Since           *time.Time `json:"since,omitempty"`
+	Types           []string   `json:"types,omitempty"`
+	UnpublishedOnly bool       `json:"unpublishedOnly,omitempty"`
+	Until           *time.Time `json:"until,omitempty"`
+}
+
+type ReplayReportResponse struct {
+	Failed   []string `json:"failed"`
+	Matched  int      `json:"matched"`
+	Replayed int      `json:"replayed"`
+}
+
+type RepriceLoanRequest struct {
+	AnnualInterestRate float64 `json:"annualInterestRate"`
+	EffectiveFrom      string  `json:"effectiveFrom"`
+}
+
+type RepriceLoansRequest struct {
+	AnnualInterestRate float64  `json:"annualInterestRate"`
+	CurrentRate        *float64 `json:"currentRate,omitempty"`
+	EffectiveFrom      string   `json:"effectiveFrom"`
+}
+
+type RepricingReportResponse struct {
+	EffectiveFrom string                  `json:"effectiveFrom"`
+	InterestRate  string                  `json:"interestRate"`
+	Repriced      int                     `json:"repriced"`
+	Skipped       []RepricingSkipResponse `json:"skipped"`
+}
+
+type RepricingResponse struct {
+	Changes             []ScheduleChangeResponse `json:"changes"`
+	LoanID              string                   `json:"loanId"`
+	RateChange          RateChangeResponse       `json:"rateChange"`
+	TotalLoanAmount     string                   `json:"totalLoanAmount"`
+	WeeklyPaymentAmount string                   `json:"weeklyPaymentAmount"`
+}
+
+type RepricingSkipResponse struct {
+	LoanID string `json:"loanId"`
+	Reason string `json:"reason"`
+}
+
+type RestructurePreviewRequest struct {
+	Amount             string  `json:"amount,omitempty"`
+	AnnualInterestRate float64 `json:"annualInterestRate,omitempty"`
+	EffectiveFrom      string  `json:"effectiveFrom,omitempty"`
+	Kind               string  `json:"kind"`
+	Option             string  `json:"option,omitempty"`
+	StartsOn           string  `json:"startsOn,omitempty"`
+	Weeks              int     `json:"weeks,omitempty"`
+}
+
+type RestructurePreviewResponse struct {
+	After   LoanTermsResponse        `json:"after"`
+	Before  LoanTermsResponse        `json:"before"`
+	Changes []ScheduleChangeResponse `json:"changes"`
+	Kind    string                   `json:"kind"`
+	LoanID  string                   `json:"loanId"`
+}
+
+type RouteSLOResponse struct {
+	BudgetBurnRate  float64 `json:"budgetBurnRate"`
+	BudgetRemaining float64 `json:"budgetRemaining"`
+	ErrorRate       float64 `json:"errorRate"`
+	Errors          int64   `json:"errors"`
+	Method          string  `json:"method"`
+	P95Ms           float64 `json:"p95Ms"`
+	P99Ms           float64 `json:"p99Ms"`
+	Requests        int64   `json:"requests"`
+	Route           string  `json:"route"`
+}
+
+type SLOStatsResponse struct {
+	BudgetBurnRate  float64 `json:"budgetBurnRate"`
+	BudgetRemaining float64 `json:"budgetRemaining"`
+	ErrorRate       float64 `json:"errorRate"`
+	Errors          int64   `json:"errors"`
+	P95Ms           float64 `json:"p95Ms"`
+	P99Ms           float64 `json:"p99Ms"`
+	Requests        int64   `json:"requests"`
+}
+
+type SLOSummaryResponse struct {
+	At        time.Time          `json:"at"`
+	Objective float64            `json:"objective"`
+	Overall   SLOStatsResponse   `json:"overall"`
+	Routes    []RouteSLOResponse `json:"routes"`
+	Window    string             `json:"window"`
+}
+
+type SandboxClockResponse struct {
+	Now        time.Time `json:"now"`
+	OffsetDays int       `json:"offsetDays"`
+	Today      string    `json:"today"`
+}
+
+type ScheduleAdjustmentResponse struct {
+	AppliedAt time.Time  `json:"appliedAt"`
+	AppliedBy *string    `json:"appliedBy,omitempty"`
+	ID        string     `json:"id"`
+	Kind      string     `json:"kind"`
+	Reason    string     `json:"reason"`
+	RemovedAt *time.Time `json:"removedAt,omitempty"`
+	RemovedBy *string    `json:"removedBy,omitempty"`
+	StartsOn  string     `json:"startsOn"`
+	Weeks     int        `json:"weeks"`
+}
+
+type ScheduleChangeResponse struct {
+	After      ScheduleEntryResponse `json:"after,omitempty"`
+	Before     ScheduleEntryResponse `json:"before,omitempty"`
+	Kind       string                `json:"kind"`
+	WeekNumber int                   `json:"weekNumber"`
+}
+
+type ScheduleEntryResponse struct {
+	DueAmount   string     `json:"dueAmount"`
+	DueDate     string     `json:"dueDate"`
+	ID          string     `json:"id"`
+	PaidAmount  *string    `json:"paidAmount,omitempty"`
+	PaymentDate *time.Time `json:"paymentDate,omitempty"`
+	Status      string     `json:"status"`
+	WeekNumber  int        `json:"weekNumber"`
+}
+
+type ScheduleRebuildResponse struct {
+	Changes  []ScheduleChangeResponse `json:"changes"`
+	DryRun   bool                     `json:"dryRun"`
+	LoanID   string                   `json:"loanId"`
+	PaidKept int                      `json:"paidKept"`
+	Status   string                   `json:"status"`
+}
+
+type SearchPaymentResponse struct {
+	Amount     string    `json:"amount"`
+	Channel    string    `json:"channel"`
+	ID         string    `json:"id"`
+	PaidAt     time.Time `json:"paidAt"`
+	Prepayment bool      `json:"prepayment"`
+	Reference  *string   `json:"reference,omitempty"`
+}
+
+type TaxLineResponse struct {
+	Amount        string `json:"amount"`
+	Jurisdiction  string `json:"jurisdiction"`
+	Rate          string `json:"rate"`
+	TaxableAmount string `json:"taxableAmount"`
+}
+
+type TaxReportResponse struct {
+	Amount  string              `json:"amount"`
+	From    string              `json:"from"`
+	Payable string              `json:"payable"`
+	Rows    []TaxTotalsResponse `json:"rows"`
+	To      string              `json:"to"`
+	Waived  string              `json:"waived"`
+}
+
+type TaxTotalsResponse struct {
+	Amount        string `json:"amount"`
+	FeeType       string `json:"feeType"`
+	Jurisdiction  string `json:"jurisdiction"`
+	Lines         int    `json:"lines"`
+	TaxableAmount string `json:"taxableAmount"`
+	WaivedAmount  string `json:"waivedAmount"`
+}
+
+type TenantSettingsResponse struct {
+	CurePayments       *int       `json:"curePayments,omitempty"`
+	GraceDays          *int       `json:"graceDays,omitempty"`
+	LateFee            *string    `json:"lateFee,omitempty"`
+	MissedPayments     *int       `json:"missedPayments,omitempty"`
+	RateLimitBurst     *int       `json:"rateLimitBurst,omitempty"`
+	RateLimitRps       *float64   `json:"rateLimitRps,omitempty"`
+	ReminderWindowDays *int       `json:"reminderWindowDays,omitempty"`
+	TenantID           string     `json:"tenantId"`
+	UpdatedAt          *time.Time `json:"updatedAt,omitempty"`
+	UpdatedBy          string     `json:"updatedBy,omitempty"`
+}
+
+type TokenRequest struct {
+	Admin      bool   `json:"admin,omitempty"`
+	CustomerID int64  `json:"customerId,omitempty"`
+	Username   string `json:"username"`
+}
+
+type UpdateContactRequest struct {
+	Primary bool   `json:"primary"`
+	Value   string `json:"value"`
+}
+
+type UpdateCustomerAddressRequest struct {
+	Address string `json:"address"`
+}
+
+type UpdateDelinquencyRequest struct {
+	IsDelinquent bool `json:"isDelinquent"`
+}
+
+type UpdatePreferencesRequest struct {
+	Language            string `json:"language,omitempty"`
+	MarketingOptOut     bool   `json:"marketingOptOut"`
+	PreferredChannel    string `json:"preferredChannel,omitempty"`
+	TransactionalOptOut bool   `json:"transactionalOptOut"`
+}
+
+type UpdateRiskScoreRequest struct {
+	Grade    string     `json:"grade"`
+	Score    *int       `json:"score"`
+	ScoredAt *time.Time `json:"scoredAt,omitempty"`
+}
+
+type UpdateTenantSettingsRequest struct {
+	CurePayments       *int     `json:"curePayments,omitempty"`
+	GraceDays          *int     `json:"graceDays,omitempty"`
+	LateFee            *string  `json:"lateFee,omitempty"`
+	MissedPayments     *int     `json:"missedPayments,omitempty"`
+	RateLimitBurst     *int     `json:"rateLimitBurst,omitempty"`
+	RateLimitRps       *float64 `json:"rateLimitRps,omitempty"`
+	ReminderWindowDays *int     `json:"reminderWindowDays,omitempty"`
+}
+
+type WorkflowLoanResponse struct {
+	Agreement AgreementResponse `json:"agreement"`
+	Loan      LoanResponse      `json:"loan"`
+}
+
+// AdvanceSandboxClock calls POST /v1/admin/sandbox/clock/advance: Advance the sandbox billing clock and run the daily jobs.
+func (c *Client) AdvanceSandboxClock(ctx context.Context, req AdvanceClockRequest) (*SandboxClockResponse, error) {
+	var out SandboxClockResponse
+	if err := c.do(ctx, "POST", "/v1/admin/sandbox/clock/advance", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// AllowlistClient calls PUT /v1/admin/ratelimit/allowlist/{principal}: Exempt a client IP from the rate limit.
+func (c *Client) AllowlistClient(ctx context.Context, principal string) (*RateLimitListsResponse, error) {
+	var out RateLimitListsResponse
+	if err := c.do(ctx, "PUT", "/v1/admin/ratelimit/allowlist/"+principal, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ApplyScheduleAdjustment calls POST /v1/loans/{loanID}/adjustments: Grant a payment holiday or a promotional zero-interest window.
+func (c *Client) ApplyScheduleAdjustment(ctx context.Context, loanID string, req ApplyAdjustmentRequest) (*AdjustmentPlanResponse, error) {
+	var out AdjustmentPlanResponse
+	if err := c.do(ctx, "POST", "/v1/loans/"+loanID+"/adjustments", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ApproveFeeWaiver calls POST /v1/loans/{loanID}/fees/{feeID}/waiver/approve: Approve a pending fee waiver.
+func (c *Client) ApproveFeeWaiver(ctx context.Context, loanID string, feeID int64) (*FeeWaiverResponse, error) {
+	var out FeeWaiverResponse
+	if err := c.do(ctx, "POST", "/v1/loans/"+loanID+"/fees/"+strconv.FormatInt(feeID, 10)+"/waiver/approve", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// AssignLoanToCustomer calls PUT /v1/customers/{customerID}/loan: Assign a loan to a customer.
+func (c *Client) AssignLoanToCustomer(ctx context.Context, customerID string, req AssignLoanRequest) error {
+	return c.do(ctx, "PUT", "/v1/customers/"+customerID+"/loan", nil, req, nil)
+}
+
+// BlockClient calls PUT /v1/admin/ratelimit/blocklist/{principal}: Refuse every request from a client IP.
+func (c *Client) BlockClient(ctx context.Context, principal string) (*RateLimitListsResponse, error) {
+	var out RateLimitListsResponse
+	if err := c.do(ctx, "PUT", "/v1/admin/ratelimit/blocklist/"+principal, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CancelMandate calls DELETE /v1/customers/{customerID}/mandates/{mandateID}: Cancel a direct-debit mandate.
+func (c *Client) CancelMandate(ctx context.Context, customerID string, mandateID int64) error {
+	return c.do(ctx, "DELETE", "/v1/customers/"+customerID+"/mandates/"+strconv.FormatInt(mandateID, 10), nil, nil, nil)
+}
+
+// ConfirmContactVerification calls POST /v1/customers/{customerID}/contacts/{contactID}/verification/confirm: Verify a contact with the code sent to it.
+func (c *Client) ConfirmContactVerification(ctx context.Context, customerID string, contactID int64, req ConfirmContactRequest) (*ContactResponse, error) {
+	var out ContactResponse
+	if err := c.do(ctx, "POST", "/v1/customers/"+customerID+"/contacts/"+strconv.FormatInt(contactID, 10)+"/verification/confirm", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ConfirmCustomerDocument calls POST /v1/customers/{customerID}/documents/{documentID}/confirm: Confirm that the file of a pending customer document was uploaded.
+func (c *Client) ConfirmCustomerDocument(ctx context.Context, customerID string, documentID int64) (*DocumentResponse, error) {
+	var out DocumentResponse
+	if err := c.do(ctx, "POST", "/v1/customers/"+customerID+"/documents/"+strconv.FormatInt(documentID, 10)+"/confirm", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ConfirmLoanDocument calls POST /v1/loans/{loanID}/documents/{documentID}/confirm: Confirm that the file of a pending loan document was uploaded.
+func (c *Client) ConfirmLoanDocument(ctx context.Context, loanID string, documentID int64) (*DocumentResponse, error) {
+	var out DocumentResponse
+	if err := c.do(ctx, "POST", "/v1/loans/"+loanID+"/documents/"+strconv.FormatInt(documentID, 10)+"/confirm", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateCollectionRule calls POST /v1/collections/rules: Create a collection rule.
+func (c *Client) CreateCollectionRule(ctx context.Context, req CreateCollectionRuleRequest) (*CollectionRuleResponse, error) {
+	var out CollectionRuleResponse
+	if err := c.do(ctx, "POST", "/v1/collections/rules", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateContact calls POST /v1/customers/{customerID}/contacts: Add an unverified phone number or email address.
+func (c *Client) CreateContact(ctx context.Context, customerID string, req CreateContactRequest) (*ContactResponse, error) {
+	var out ContactResponse
+	if err := c.do(ctx, "POST", "/v1/customers/"+customerID+"/contacts", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateCustomer calls POST /v1/customers: Create a new customer.
+func (c *Client) CreateCustomer(ctx context.Context, req CreateCustomerRequest) (*CustomerResponse, error) {
+	var out CustomerResponse
+	if err := c.do(ctx, "POST", "/v1/customers", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateCustomerAttachment calls POST /v1/customers/{customerID}/attachments: Upload an attachment to a customer.
+func (c *Client) CreateCustomerAttachment(ctx context.Context, customerID string, contentType string, body io.Reader) (*AttachmentResponse, error) {
+	var out AttachmentResponse
+	if err := c.do(ctx, "POST", "/v1/customers/"+customerID+"/attachments", nil, rawBody{contentType: contentType, r: body}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateCustomerNote calls POST /v1/customers/{customerID}/notes: Add a note to a customer.
+func (c *Client) CreateCustomerNote(ctx context.Context, customerID string, req CreateNoteRequest) (*NoteResponse, error) {
+	var out NoteResponse
+	if err := c.do(ctx, "POST", "/v1/customers/"+customerID+"/notes", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateLoan calls POST /v1/loans: Create a new loan.
+func (c *Client) CreateLoan(ctx context.Context, req CreateLoanRequest) (*LoanResponse, error) {
+	var out LoanResponse
+	if err := c.do(ctx, "POST", "/v1/loans", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateLoanAttachment calls POST /v1/loans/{loanID}/attachments: Upload an attachment to a loan.
+func (c *Client) CreateLoanAttachment(ctx context.Context, loanID string, contentType string, body io.Reader) (*AttachmentResponse, error) {
+	var out AttachmentResponse
+	if err := c.do(ctx, "POST", "/v1/loans/"+loanID+"/attachments", nil, rawBody{contentType: contentType, r: body}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateLoanNote calls POST /v1/loans/{loanID}/notes: Add a note to a loan.
+func (c *Client) CreateLoanNote(ctx context.Context, loanID string, req CreateNoteRequest) (*NoteResponse, error) {
+	var out NoteResponse
+	if err := c.do(ctx, "POST", "/v1/loans/"+loanID+"/notes", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateMandate calls POST /v1/customers/{customerID}/mandates: Register a direct-debit mandate.
+func (c *Client) CreateMandate(ctx context.Context, customerID string, req CreateMandateRequest) (*MandateResponse, error) {
+	var out MandateResponse
+	if err := c.do(ctx, "POST", "/v1/customers/"+customerID+"/mandates", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateWorkflowLoan calls POST /v1/loans/workflow: Create a loan on hold and send its agreement for e-signature.
+func (c *Client) CreateWorkflowLoan(ctx context.Context, req CreateLoanRequest) (*WorkflowLoanResponse, error) {
+	var out WorkflowLoanResponse
+	if err := c.do(ctx, "POST", "/v1/loans/workflow", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeactivateCustomer calls DELETE /v1/customers/{customerID}: Deactivate a customer.
+func (c *Client) DeactivateCustomer(ctx context.Context, customerID string) error {
+	return c.do(ctx, "DELETE", "/v1/customers/"+customerID, nil, nil, nil)
+}
+
+// DeleteCollectionRule calls DELETE /v1/collections/rules/{ruleID}: Delete a collection rule.
+func (c *Client) DeleteCollectionRule(ctx context.Context, ruleID int64) error {
+	return c.do(ctx, "DELETE", "/v1/collections/rules/"+strconv.FormatInt(ruleID, 10), nil, nil, nil)
+}
+
+// DeleteContact calls DELETE /v1/customers/{customerID}/contacts/{contactID}: Delete a contact.
+func (c *Client) DeleteContact(ctx context.Context, customerID string, contactID int64) error {
+	return c.do(ctx, "DELETE", "/v1/customers/"+customerID+"/contacts/"+strconv.FormatInt(contactID, 10), nil, nil, nil)
+}
+
+// DeleteCustomerAttachment calls DELETE /v1/customers/{customerID}/attachments/{attachmentID}: Delete an attachment of a customer.
+func (c *Client) DeleteCustomerAttachment(ctx context.Context, customerID string, attachmentID int64) error {
+	return c.do(ctx, "DELETE", "/v1/customers/"+customerID+"/attachments/"+strconv.FormatInt(attachmentID, 10), nil, nil, nil)
+}
+
+// DeleteCustomerDocument calls DELETE /v1/customers/{customerID}/documents/{documentID}: Delete an uploaded document of a customer.
+func (c *Client) DeleteCustomerDocument(ctx context.Context, customerID string, documentID int64) error {
+	return c.do(ctx, "DELETE", "/v1/customers/"+customerID+"/documents/"+strconv.FormatInt(documentID, 10), nil, nil, nil)
+}
+
+// DeleteCustomerNote calls DELETE /v1/customers/{customerID}/notes/{noteID}: Delete a note of a customer.
+func (c *Client) DeleteCustomerNote(ctx context.Context, customerID string, noteID int64) error {
+	return c.do(ctx, "DELETE", "/v1/customers/"+customerID+"/notes/"+strconv.FormatInt(noteID, 10), nil, nil, nil)
+}
+
+// DeleteLoanAttachment calls DELETE /v1/loans/{loanID}/attachments/{attachmentID}: Delete an attachment of a loan.
+func (c *Client) DeleteLoanAttachment(ctx context.Context, loanID string, attachmentID int64) error {
+	return c.do(ctx, "DELETE", "/v1/loans/"+loanID+"/attachments/"+strconv.FormatInt(attachmentID, 10), nil, nil, nil)
+}
+
+// DeleteLoanDocument calls DELETE /v1/loans/{loanID}/documents/{documentID}: Delete an uploaded document of a loan.
+func (c *Client) DeleteLoanDocument(ctx context.Context, loanID string, documentID int64) error {
+	return c.do(ctx, "DELETE", "/v1/loans/"+loanID+"/documents/"+strconv.FormatInt(documentID, 10), nil, nil, nil)
+}
+
+// DeleteLoanNote calls DELETE /v1/loans/{loanID}/notes/{noteID}: Delete a note of a loan.
+func (c *Client) DeleteLoanNote(ctx context.Context, loanID string, noteID int64) error {
+	return c.do(ctx, "DELETE", "/v1/loans/"+loanID+"/notes/"+strconv.FormatInt(noteID, 10), nil, nil, nil)
+}
+
+// DisburseLoan calls POST /v1/loans/{loanID}/disbursement: Disburse a workflow loan whose agreement is signed.
+func (c *Client) DisburseLoan(ctx context.Context, loanID string) (*AgreementResponse, error) {
+	var out AgreementResponse
+	if err := c.do(ctx, "POST", "/v1/loans/"+loanID+"/disbursement", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// FindCustomerByLoan calls GET /v1/customers: Find customer by loan ID or external reference.
+func (c *Client) FindCustomerByLoan(ctx context.Context, loanID int64, externalRef string) (*CustomerResponse, error) {
+	query := url.Values{}
+	if loanID != 0 {
+		query.Set("loan_id", strconv.FormatInt(loanID, 10))
+	}
+	if externalRef != "" {
+		query.Set("external_ref", externalRef)
+	}
+	var out CustomerResponse
+	if err := c.do(ctx, "GET", "/v1/customers", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// FindLoanByExternalRef calls GET /v1/loans: Find loan by external reference.
+func (c *Client) FindLoanByExternalRef(ctx context.Context, externalRef string, include string) (*LoanResponse, error) {
+	query := url.Values{}
+	query.Set("external_ref", externalRef)
+	if include != "" {
+		query.Set("include", include)
+	}
+	var out LoanResponse
+	if err := c.do(ctx, "GET", "/v1/loans", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GenerateToken calls POST /v1/auth/token: Generate a JWT bearer token.
+func (c *Client) GenerateToken(ctx context.Context, req TokenRequest) (map[string]string, error) {
+	var out map[string]string
+	if err := c.do(ctx, "POST", "/v1/auth/token", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetCollectionQueue calls GET /v1/collections/queue: Get a collector's queue.
+func (c *Client) GetCollectionQueue(ctx context.Context, collector string) ([]CollectionAssignmentResponse, error) {
+	query := url.Values{}
+	if collector != "" {
+		query.Set("collector", collector)
+	}
+	var out []CollectionAssignmentResponse
+	if err := c.do(ctx, "GET", "/v1/collections/queue", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetCollectionsByChannel calls GET /v1/reports/collections-by-channel: Total the payments received by channel.
+func (c *Client) GetCollectionsByChannel(ctx context.Context, from string, to string) (*CollectionsByChannelResponse, error) {
+	query := url.Values{}
+	if from != "" {
+		query.Set("from", from)
+	}
+	if to != "" {
+		query.Set("to", to)
+	}
+	var out CollectionsByChannelResponse
+	if err := c.do(ctx, "GET", "/v1/reports/collections-by-channel", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetCustomer calls GET /v1/customers/{customerID}: Retrieve customer details.
+func (c *Client) GetCustomer(ctx context.Context, customerID string) (*CustomerResponse, error) {
+	var out CustomerResponse
+	if err := c.do(ctx, "GET", "/v1/customers/"+customerID, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetCustomerOverview calls GET /v1/customers/{customerID}/overview: Get a customer's overview for support.
+func (c *Client) GetCustomerOverview(ctx context.Context, customerID string) (*CustomerOverviewResponse, error) {
+	var out CustomerOverviewResponse
+	if err := c.do(ctx, "GET", "/v1/customers/"+customerID+"/overview", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetCustomerPreferences calls GET /v1/customers/{customerID}/preferences: Get customer communication preferences.
+func (c *Client) GetCustomerPreferences(ctx context.Context, customerID string) (*PreferencesResponse, error) {
+	var out PreferencesResponse
+	if err := c.do(ctx, "GET", "/v1/customers/"+customerID+"/preferences", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetCustomerSummary calls GET /v1/customers/{customerID}/summary: Get a customer's loan summary.
+func (c *Client) GetCustomerSummary(ctx context.Context, customerID string) (*CustomerSummaryResponse, error) {
+	var out CustomerSummaryResponse
+	if err := c.do(ctx, "GET", "/v1/customers/"+customerID+"/summary", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetJob calls GET /v1/jobs/{jobID}: Get the progress and result of an async job.
+func (c *Client) GetJob(ctx context.Context, jobID string) (*JobResponse, error) {
+	var out JobResponse
+	if err := c.do(ctx, "GET", "/v1/jobs/"+jobID, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetJobRun calls GET /v1/admin/jobs/{name}/runs/{runID}: Get one run of a batch job.
+func (c *Client) GetJobRun(ctx context.Context, name string, runID int64) (*JobRunResponse, error) {
+	var out JobRunResponse
+	if err := c.do(ctx, "GET", "/v1/admin/jobs/"+name+"/runs/"+strconv.FormatInt(runID, 10), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetLoan calls GET /v1/loans/{loanID}: Retrieve loan details.
+func (c *Client) GetLoan(ctx context.Context, loanID string, include string) (*LoanResponse, error) {
+	query := url.Values{}
+	if include != "" {
+		query.Set("include", include)
+	}
+	var out LoanResponse
+	if err := c.do(ctx, "GET", "/v1/loans/"+loanID, query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetLoanAgreement calls GET /v1/loans/{loanID}/agreement: Get the most recent agreement of a workflow loan.
+func (c *Client) GetLoanAgreement(ctx context.Context, loanID string) (*AgreementResponse, error) {
+	var out AgreementResponse
+	if err := c.do(ctx, "GET", "/v1/loans/"+loanID+"/agreement", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetLoanHistory calls GET /v1/loans/{loanID}/history: Retrieve daily loan status snapshots.
+func (c *Client) GetLoanHistory(ctx context.Context, loanID string, from string, to string) (*LoanHistoryResponse, error) {
+	query := url.Values{}
+	if from != "" {
+		query.Set("from", from)
+	}
+	if to != "" {
+		query.Set("to", to)
+	}
+	var out LoanHistoryResponse
+	if err := c.do(ctx, "GET", "/v1/loans/"+loanID+"/history", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetOutstanding calls GET /v1/loans/{loanID}/outstanding: Retrieve outstanding loan amount.
+func (c *Client) GetOutstanding(ctx context.Context, loanID string) (*OutstandingResponse, error) {
+	var out OutstandingResponse
+	if err := c.do(ctx, "GET", "/v1/loans/"+loanID+"/outstanding", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetPortfolio calls GET /v1/reports/portfolio: Retrieve the portfolio as of a date.
+func (c *Client) GetPortfolio(ctx context.Context, date string) (*PortfolioResponse, error) {
+	query := url.Values{}
+	if date != "" {
+		query.Set("date", date)
+	}
+	var out PortfolioResponse
+	if err := c.do(ctx, "GET", "/v1/reports/portfolio", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetRateLimitLists calls GET /v1/admin/ratelimit/lists: Get the blocked and the allowlisted client IPs.
+func (c *Client) GetRateLimitLists(ctx context.Context) (*RateLimitListsResponse, error) {
+	var out RateLimitListsResponse
+	if err := c.do(ctx, "GET", "/v1/admin/ratelimit/lists", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetSLOSummary calls GET /v1/admin/slo: Get rolling latency quantiles and error budget burn per route.
+func (c *Client) GetSLOSummary(ctx context.Context) (*SLOSummaryResponse, error) {
+	var out SLOSummaryResponse
+	if err := c.do(ctx, "GET", "/v1/admin/slo", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetSandboxClock calls GET /v1/admin/sandbox/clock: Get the sandbox billing clock.
+func (c *Client) GetSandboxClock(ctx context.Context) (*SandboxClockResponse, error) {
+	var out SandboxClockResponse
+	if err := c.do(ctx, "GET", "/v1/admin/sandbox/clock", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetTaxReport calls GET /v1/reports/tax: Total the tax charged on fees by jurisdiction and fee type.
+func (c *Client) GetTaxReport(ctx context.Context, from string, to string) (*TaxReportResponse, error) {
+	query := url.Values{}
+	if from != "" {
+		query.Set("from", from)
+	}
+	if to != "" {
+		query.Set("to", to)
+	}
+	var out TaxReportResponse
+	if err := c.do(ctx, "GET", "/v1/reports/tax", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetTenantSettings calls GET /v1/admin/tenants/{tenantID}/settings: Get the delinquency thresholds and rate limit a tenant overrides.
+func (c *Client) GetTenantSettings(ctx context.Context, tenantID string) (*TenantSettingsResponse, error) {
+	var out TenantSettingsResponse
+	if err := c.do(ctx, "GET", "/v1/admin/tenants/"+tenantID+"/settings", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GraphQLQuery calls POST /v1/graphql: Execute a read-only GraphQL query.
+func (c *Client) GraphQLQuery(ctx context.Context, req GraphQLRequest) (*GraphQLResponse, error) {
+	var out GraphQLResponse
+	if err := c.do(ctx, "POST", "/v1/graphql", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// HandleESignCallback calls POST /v1/esign/callback: Apply an e-signature provider callback, signed in X-Signature.
+func (c *Client) HandleESignCallback(ctx context.Context, req map[string]string) (*AgreementResponse, error) {
+	var out AgreementResponse
+	if err := c.do(ctx, "POST", "/v1/esign/callback", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ImportCustomers calls POST /v1/customers/import: Import customers in bulk.
+func (c *Client) ImportCustomers(ctx context.Context, contentType string, body io.Reader) (*CustomerImportResponse, error) {
+	var out CustomerImportResponse
+	if err := c.do(ctx, "POST", "/v1/customers/import", nil, rawBody{contentType: contentType, r: body}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// IsDelinquent calls GET /v1/loans/{loanID}/delinquent: Check loan delinquency status.
+func (c *Client) IsDelinquent(ctx context.Context, loanID string) (*DelinquentResponse, error) {
+	var out DelinquentResponse
+	if err := c.do(ctx, "GET", "/v1/loans/"+loanID+"/delinquent", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListCollectionActions calls GET /v1/collections/assignments/{assignmentID}/actions: List collection actions.
+func (c *Client) ListCollectionActions(ctx context.Context, assignmentID int64) ([]CollectionActionResponse, error) {
+	var out []CollectionActionResponse
+	if err := c.do(ctx, "GET", "/v1/collections/assignments/"+strconv.FormatInt(assignmentID, 10)+"/actions", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListCollectionRules calls GET /v1/collections/rules: List collection rules.
+func (c *Client) ListCollectionRules(ctx context.Context) ([]CollectionRuleResponse, error) {
+	var out []CollectionRuleResponse
+	if err := c.do(ctx, "GET", "/v1/collections/rules", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListContacts calls GET /v1/customers/{customerID}/contacts: List the phone numbers and email addresses of a customer.
+func (c *Client) ListContacts(ctx context.Context, customerID string) ([]ContactResponse, error) {
+	var out []ContactResponse
+	if err := c.do(ctx, "GET", "/v1/customers/"+customerID+"/contacts", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListCustomerAttachments calls GET /v1/customers/{customerID}/attachments: List the attachments of a customer.
+func (c *Client) ListCustomerAttachments(ctx context.Context, customerID string) ([]AttachmentResponse, error) {
+	var out []AttachmentResponse
+	if err := c.do(ctx, "GET", "/v1/customers/"+customerID+"/attachments", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListCustomerDocuments calls GET /v1/customers/{customerID}/documents: List the documents of a customer.
+func (c *Client) ListCustomerDocuments(ctx context.Context, customerID string) ([]DocumentResponse, error) {
+	var out []DocumentResponse
+	if err := c.do(ctx, "GET", "/v1/customers/"+customerID+"/documents", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListCustomerNotes calls GET /v1/customers/{customerID}/notes: List the notes of a customer.
+func (c *Client) ListCustomerNotes(ctx context.Context, customerID string) ([]NoteResponse, error) {
+	var out []NoteResponse
+	if err := c.do(ctx, "GET", "/v1/customers/"+customerID+"/notes", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListDeprecatedUsage calls GET /v1/admin/deprecations: List the clients still calling deprecated routes and how often.
+func (c *Client) ListDeprecatedUsage(ctx context.Context, limit int) ([]DeprecatedUsageResponse, error) {
+	query := url.Values{}
+	if limit != 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	var out []DeprecatedUsageResponse
+	if err := c.do(ctx, "GET", "/v1/admin/deprecations", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListEscalationSteps calls GET /v1/collections/escalation-steps: List the reminder ladder.
+func (c *Client) ListEscalationSteps(ctx context.Context) ([]EscalationStepResponse, error) {
+	var out []EscalationStepResponse
+	if err := c.do(ctx, "GET", "/v1/collections/escalation-steps", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListFeeTypes calls GET /v1/loans/fee-types: List the fee catalog.
+func (c *Client) ListFeeTypes(ctx context.Context) ([]FeeTypeResponse, error) {
+	var out []FeeTypeResponse
+	if err := c.do(ctx, "GET", "/v1/loans/fee-types", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListIntegrityFindings calls GET /v1/admin/integrity/findings: List the data integrity violations found by the last integrity check run.
+func (c *Client) ListIntegrityFindings(ctx context.Context, check string) ([]IntegrityFindingResponse, error) {
+	query := url.Values{}
+	if check != "" {
+		query.Set("check", check)
+	}
+	var out []IntegrityFindingResponse
+	if err := c.do(ctx, "GET", "/v1/admin/integrity/findings", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListJobRuns calls GET /v1/admin/jobs/{name}/runs: List the latest runs of a batch job with their progress, error samples and duration.
+func (c *Client) ListJobRuns(ctx context.Context, name string, limit int) ([]JobRunResponse, error) {
+	query := url.Values{}
+	if limit != 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	var out []JobRunResponse
+	if err := c.do(ctx, "GET", "/v1/admin/jobs/"+name+"/runs", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListJobs calls GET /v1/jobs: List async jobs, newest first.
+func (c *Client) ListJobs(ctx context.Context, kind string, status string, limit int) ([]JobResponse, error) {
+	query := url.Values{}
+	if kind != "" {
+		query.Set("kind", kind)
+	}
+	if status != "" {
+		query.Set("status", status)
+	}
+	if limit != 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	var out []JobResponse
+	if err := c.do(ctx, "GET", "/v1/jobs", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListLoanAttachments calls GET /v1/loans/{loanID}/attachments: List the attachments of a loan.
+func (c *Client) ListLoanAttachments(ctx context.Context, loanID string) ([]AttachmentResponse, error) {
+	var out []AttachmentResponse
+	if err := c.do(ctx, "GET", "/v1/loans/"+loanID+"/attachments", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListLoanDocuments calls GET /v1/loans/{loanID}/documents: List the documents of a loan.
+func (c *Client) ListLoanDocuments(ctx context.Context, loanID string) ([]DocumentResponse, error) {
+	var out []DocumentResponse
+	if err := c.do(ctx, "GET", "/v1/loans/"+loanID+"/documents", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListLoanFees calls GET /v1/loans/{loanID}/fees: List a loan's fees and their waivers.
+func (c *Client) ListLoanFees(ctx context.Context, loanID string) ([]FeeResponse, error) {
+	var out []FeeResponse
+	if err := c.do(ctx, "GET", "/v1/loans/"+loanID+"/fees", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListLoanNotes calls GET /v1/loans/{loanID}/notes: List the notes of a loan.
+func (c *Client) ListLoanNotes(ctx context.Context, loanID string) ([]NoteResponse, error) {
+	var out []NoteResponse
+	if err := c.do(ctx, "GET", "/v1/loans/"+loanID+"/notes", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListMandates calls GET /v1/customers/{customerID}/mandates: List the direct-debit mandates of a customer.
+func (c *Client) ListMandates(ctx context.Context, customerID string) ([]MandateResponse, error) {
+	var out []MandateResponse
+	if err := c.do(ctx, "GET", "/v1/customers/"+customerID+"/mandates", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListRateLimitConsumers calls GET /v1/admin/ratelimit/consumers: List the client IPs with the most requests and how many were rate limited.
+func (c *Client) ListRateLimitConsumers(ctx context.Context, limit int) ([]RateLimitConsumerResponse, error) {
+	query := url.Values{}
+	if limit != 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	var out []RateLimitConsumerResponse
+	if err := c.do(ctx, "GET", "/v1/admin/ratelimit/consumers", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListRecordedEvents calls GET /v1/admin/events: List recorded events a replay with the same criteria would publish.
+func (c *Client) ListRecordedEvents(ctx context.Context, entityID int64, types string, since string, until string, unpublished bool, limit int) ([]EventRecordResponse, error) {
+	query := url.Values{}
+	if entityID != 0 {
+		query.Set("entity_id", strconv.FormatInt(entityID, 10))
+	}
+	if types != "" {
+		query.Set("types", types)
+	}
+	if since != "" {
+		query.Set("since", since)
+	}
+	if until != "" {
+		query.Set("until", until)
+	}
+	if unpublished {
+		query.Set("unpublished", strconv.FormatBool(unpublished))
+	}
+	if limit != 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	var out []EventRecordResponse
+	if err := c.do(ctx, "GET", "/v1/admin/events", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MakePayment calls POST /v1/loans/{loanID}/payments: Make a loan payment.
+func (c *Client) MakePayment(ctx context.Context, loanID string, req MakePaymentRequest) (map[string]string, error) {
+	var out map[string]string
+	if err := c.do(ctx, "POST", "/v1/loans/"+loanID+"/payments", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MergeCustomer calls POST /v1/customers/{customerID}/merge: Merge a duplicate customer into this one.
+func (c *Client) MergeCustomer(ctx context.Context, customerID string, req MergeCustomerRequest) (*CustomerMergeResponse, error) {
+	var out CustomerMergeResponse
+	if err := c.do(ctx, "POST", "/v1/customers/"+customerID+"/merge", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// MyLoans calls GET /v1/me/loans: List my loans.
+func (c *Client) MyLoans(ctx context.Context) ([]LoanResponse, error) {
+	var out []LoanResponse
+	if err := c.do(ctx, "GET", "/v1/me/loans", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MyOutstanding calls GET /v1/me/outstanding: Retrieve my outstanding amount.
+func (c *Client) MyOutstanding(ctx context.Context) (*OutstandingResponse, error) {
+	var out OutstandingResponse
+	if err := c.do(ctx, "GET", "/v1/me/outstanding", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// MySchedule calls GET /v1/me/schedule: Retrieve my repayment schedule.
+func (c *Client) MySchedule(ctx context.Context) ([]ScheduleEntryResponse, error) {
+	var out []ScheduleEntryResponse
+	if err := c.do(ctx, "GET", "/v1/me/schedule", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PlaceLoanHold calls POST /v1/loans/{loanID}/hold: Place a loan on hold.
+func (c *Client) PlaceLoanHold(ctx context.Context, loanID string, req PlaceHoldRequest) (*LoanHoldResponse, error) {
+	var out LoanHoldResponse
+	if err := c.do(ctx, "POST", "/v1/loans/"+loanID+"/hold", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PostLoanFee calls POST /v1/loans/{loanID}/fees: Post an ad-hoc fee to a loan.
+func (c *Client) PostLoanFee(ctx context.Context, loanID string, req PostFeeRequest) (*FeeResponse, error) {
+	var out FeeResponse
+	if err := c.do(ctx, "POST", "/v1/loans/"+loanID+"/fees", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PrepayLoan calls POST /v1/loans/{loanID}/prepayments: Prepay principal and reamortize the remaining schedule.
+func (c *Client) PrepayLoan(ctx context.Context, loanID string, req PrepaymentRequest) (*ReamortizationResponse, error) {
+	var out ReamortizationResponse
+	if err := c.do(ctx, "POST", "/v1/loans/"+loanID+"/prepayments", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PreviewLoanRestructure calls POST /v1/loans/{loanID}/restructure/preview: Preview the schedule and terms a restructure would give a loan.
+func (c *Client) PreviewLoanRestructure(ctx context.Context, loanID string, req RestructurePreviewRequest) (*RestructurePreviewResponse, error) {
+	var out RestructurePreviewResponse
+	if err := c.do(ctx, "POST", "/v1/loans/"+loanID+"/restructure/preview", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ProcessDirectDebitResults calls POST /v1/direct-debit/results: Process a bank result file.
+func (c *Client) ProcessDirectDebitResults(ctx context.Context, contentType string, body io.Reader) (*DirectDebitResultsResponse, error) {
+	var out DirectDebitResultsResponse
+	if err := c.do(ctx, "POST", "/v1/direct-debit/results", nil, rawBody{contentType: contentType, r: body}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ReactivateCustomer calls PUT /v1/customers/{customerID}/reactivate: Reactivate a customer.
+func (c *Client) ReactivateCustomer(ctx context.Context, customerID string) error {
+	return c.do(ctx, "PUT", "/v1/customers/"+customerID+"/reactivate", nil, nil, nil)
+}
+
+// ReassignCollection calls PUT /v1/collections/assignments/{assignmentID}/collector: Reassign a loan to another collector.
+func (c *Client) ReassignCollection(ctx context.Context, assignmentID int64, req ReassignCollectionRequest) (*CollectionAssignmentResponse, error) {
+	var out CollectionAssignmentResponse
+	if err := c.do(ctx, "PUT", "/v1/collections/assignments/"+strconv.FormatInt(assignmentID, 10)+"/collector", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RebuildLoanSchedule calls POST /v1/admin/loans/{loanID}/schedule/rebuild: Regenerate a loan's unpaid installments from its terms, or preview the changes with dry_run.
+func (c *Client) RebuildLoanSchedule(ctx context.Context, loanID string, dryRun bool) (*ScheduleRebuildResponse, error) {
+	query := url.Values{}
+	if dryRun {
+		query.Set("dry_run", strconv.FormatBool(dryRun))
+	}
+	var out ScheduleRebuildResponse
+	if err := c.do(ctx, "POST", "/v1/admin/loans/"+loanID+"/schedule/rebuild", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RecordCollectionAction calls POST /v1/collections/assignments/{assignmentID}/actions: Record a collection action.
+func (c *Client) RecordCollectionAction(ctx context.Context, assignmentID int64, req RecordCollectionActionRequest) (*CollectionActionResponse, error) {
+	var out CollectionActionResponse
+	if err := c.do(ctx, "POST", "/v1/collections/assignments/"+strconv.FormatInt(assignmentID, 10)+"/actions", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RejectFeeWaiver calls POST /v1/loans/{loanID}/fees/{feeID}/waiver/reject: Reject a pending fee waiver.
+func (c *Client) RejectFeeWaiver(ctx context.Context, loanID string, feeID int64) (*FeeWaiverResponse, error) {
+	var out FeeWaiverResponse
+	if err := c.do(ctx, "POST", "/v1/loans/"+loanID+"/fees/"+strconv.FormatInt(feeID, 10)+"/waiver/reject", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ReleaseLoanHold calls DELETE /v1/loans/{loanID}/hold: Release a loan hold.
+func (c *Client) ReleaseLoanHold(ctx context.Context, loanID string) (*LoanHoldResponse, error) {
+	var out LoanHoldResponse
+	if err := c.do(ctx, "DELETE", "/v1/loans/"+loanID+"/hold", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RemoveAllowlistedClient calls DELETE /v1/admin/ratelimit/allowlist/{principal}: Rate limit a client IP again.
+func (c *Client) RemoveAllowlistedClient(ctx context.Context, principal string) (*RateLimitListsResponse, error) {
+	var out RateLimitListsResponse
+	if err := c.do(ctx, "DELETE", "/v1/admin/ratelimit/allowlist/"+principal, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RemoveScheduleAdjustment calls DELETE /v1/loans/{loanID}/adjustments/{adjustmentID}: Withdraw a schedule adjustment that has not started.
+func (c *Client) RemoveScheduleAdjustment(ctx context.Context, loanID string, adjustmentID int64) (*AdjustmentPlanResponse, error) {
+	var out AdjustmentPlanResponse
+	if err := c.do(ctx, "DELETE", "/v1/loans/"+loanID+"/adjustments/"+strconv.FormatInt(adjustmentID, 10), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ReplaceEscalationSteps calls PUT /v1/collections/escalation-steps: Replace the reminder ladder.
+func (c *Client) ReplaceEscalationSteps(ctx context.Context, req ReplaceEscalationStepsRequest) ([]EscalationStepResponse, error) {
+	var out []EscalationStepResponse
+	if err := c.do(ctx, "PUT", "/v1/collections/escalation-steps", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ReplayEvents calls POST /v1/admin/events/replay: Publish recorded events to the broker again.
+func (c *Client) ReplayEvents(ctx context.Context, req ReplayEventsRequest) (*ReplayReportResponse, error) {
+	var out ReplayReportResponse
+	if err := c.do(ctx, "POST", "/v1/admin/events/replay", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RepriceLoan calls POST /v1/loans/{loanID}/rate: Change a loan's interest rate from an effective date.
+func (c *Client) RepriceLoan(ctx context.Context, loanID string, req RepriceLoanRequest) (*RepricingResponse, error) {
+	var out RepricingResponse
+	if err := c.do(ctx, "POST", "/v1/loans/"+loanID+"/rate", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RepriceLoans calls POST /v1/admin/loans/repricing: Apply a base-rate change to the loans charged a rate, or to every loan not paid off.
+func (c *Client) RepriceLoans(ctx context.Context, req RepriceLoansRequest) (*RepricingReportResponse, error) {
+	var out RepricingReportResponse
+	if err := c.do(ctx, "POST", "/v1/admin/loans/repricing", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RequestFeeWaiver calls POST /v1/loans/{loanID}/fees/{feeID}/waiver: Request a fee waiver.
+func (c *Client) RequestFeeWaiver(ctx context.Context, loanID string, feeID int64, req FeeWaiverRequest) (*FeeWaiverResponse, error) {
+	var out FeeWaiverResponse
+	if err := c.do(ctx, "POST", "/v1/loans/"+loanID+"/fees/"+strconv.FormatInt(feeID, 10)+"/waiver", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SearchLoans calls GET /v1/loans/search: Search loans by payment reference, amount or customer name.
+func (c *Client) SearchLoans(ctx context.Context, reference string, amount float64, tolerance float64, from string, to string, customerName string, limit int) ([]LoanSearchMatchResponse, error) {
+	query := url.Values{}
+	if reference != "" {
+		query.Set("reference", reference)
+	}
+	if amount != 0 {
+		query.Set("amount", fmt.Sprint(amount))
+	}
+	if tolerance != 0 {
+		query.Set("tolerance", fmt.Sprint(tolerance))
+	}
+	if from != "" {
+		query.Set("from", from)
+	}
+	if to != "" {
+		query.Set("to", to)
+	}
+	if customerName != "" {
+		query.Set("customer_name", customerName)
+	}
+	if limit != 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	var out []LoanSearchMatchResponse
+	if err := c.do(ctx, "GET", "/v1/loans/search", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SendContactVerification calls POST /v1/customers/{customerID}/contacts/{contactID}/verification: Send a verification code to a contact.
+func (c *Client) SendContactVerification(ctx context.Context, customerID string, contactID int64) (*ContactVerificationResponse, error) {
+	var out ContactVerificationResponse
+	if err := c.do(ctx, "POST", "/v1/customers/"+customerID+"/contacts/"+strconv.FormatInt(contactID, 10)+"/verification", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SendLoanAgreement calls POST /v1/loans/{loanID}/agreement: Send a loan a new agreement for e-signature.
+func (c *Client) SendLoanAgreement(ctx context.Context, loanID string) (*AgreementResponse, error) {
+	var out AgreementResponse
+	if err := c.do(ctx, "POST", "/v1/loans/"+loanID+"/agreement", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UnblockClient calls DELETE /v1/admin/ratelimit/blocklist/{principal}: Take a client IP off the blocklist.
+func (c *Client) UnblockClient(ctx context.Context, principal string) (*RateLimitListsResponse, error) {
+	var out RateLimitListsResponse
+	if err := c.do(ctx, "DELETE", "/v1/admin/ratelimit/blocklist/"+principal, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateContact calls PUT /v1/customers/{customerID}/contacts/{contactID}: Change a contact's value or make it primary.
+func (c *Client) UpdateContact(ctx context.Context, customerID string, contactID int64, req UpdateContactRequest) (*ContactResponse, error) {
+	var out ContactResponse
+	if err := c.do(ctx, "PUT", "/v1/customers/"+customerID+"/contacts/"+strconv.FormatInt(contactID, 10), nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateCustomerAddress calls PUT /v1/customers/{customerID}/address: Update customer address.
+func (c *Client) UpdateCustomerAddress(ctx context.Context, customerID string, req UpdateCustomerAddressRequest) error {
+	return c.do(ctx, "PUT", "/v1/customers/"+customerID+"/address", nil, req, nil)
+}
+
+// UpdateCustomerPreferences calls PUT /v1/customers/{customerID}/preferences: Replace customer communication preferences.
+func (c *Client) UpdateCustomerPreferences(ctx context.Context, customerID string, req UpdatePreferencesRequest) (*PreferencesResponse, error) {
+	var out PreferencesResponse
+	if err := c.do(ctx, "PUT", "/v1/customers/"+customerID+"/preferences", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateCustomerRiskScore calls PUT /v1/customers/{customerID}/risk-score: Store the risk score a credit bureau gave a customer.
+func (c *Client) UpdateCustomerRiskScore(ctx context.Context, customerID string, req UpdateRiskScoreRequest) (*CustomerResponse, error) {
+	var out CustomerResponse
+	if err := c.do(ctx, "PUT", "/v1/customers/"+customerID+"/risk-score", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateDelinquency calls PUT /v1/customers/{customerID}/delinquency: Update customer delinquency status.
+func (c *Client) UpdateDelinquency(ctx context.Context, customerID string, req UpdateDelinquencyRequest) error {
+	return c.do(ctx, "PUT", "/v1/customers/"+customerID+"/delinquency", nil, req, nil)
+}
+
+// UpdateTenantSettings calls PUT /v1/admin/tenants/{tenantID}/settings: Replace a tenant's overrides of the delinquency thresholds and rate limit.
+func (c *Client) UpdateTenantSettings(ctx context.Context, tenantID string, req UpdateTenantSettingsRequest) (*TenantSettingsResponse, error) {
+	var out TenantSettingsResponse
+	if err := c.do(ctx, "PUT", "/v1/admin/tenants/"+tenantID+"/settings", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UploadCustomerDocument calls POST /v1/customers/{customerID}/documents: Start a document upload to a customer and get its pre-signed upload link.
+func (c *Client) UploadCustomerDocument(ctx context.Context, customerID string, req CreateDocumentRequest) (*DocumentUploadResponse, error) {
+	var out DocumentUploadResponse
+	if err := c.do(ctx, "POST", "/v1/customers/"+customerID+"/documents", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UploadLoanDocument calls POST /v1/loans/{loanID}/documents: Start a document upload to a loan and get its pre-signed upload link.
+func (c *Client) UploadLoanDocument(ctx context.Context, loanID string, req CreateDocumentRequest) (*DocumentUploadResponse, error) {
+	var out DocumentUploadResponse
+	if err := c.do(ctx, "POST", "/v1/loans/"+loanID+"/documents", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
@@ -0,0 +1,96 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientMakePayment(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/v1/loans/7/payments", r.URL.Path)
+		assert.Equal(t, "Bearer abc", r.Header.Get("Authorization"))
+
+		var req MakePaymentRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "110.00", req.Amount)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"message":"Payment successful"}`))
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, WithToken("abc"))
+	resp, err := c.MakePayment(context.Background(), "7", MakePaymentRequest{Amount: "110.00"})
+
+	require.NoError(t, err)
+	assert.Equal(t, "Payment successful", resp["message"])
+}
+
+func TestClientQueryAndNoContent(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/customers":
+			assert.Equal(t, "12", r.URL.Query().Get("loan_id"))
+			w.Write([]byte(`{"customerId":"3","name":"John","loanId":"12"}`))
+		case "/v1/customers/3/reactivate":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Fatalf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL + "/")
+
+	cust, err := c.FindCustomerByLoan(context.Background(), 12, "")
+	require.NoError(t, err)
+	assert.Equal(t, "3", cust.CustomerID)
+	require.NotNil(t, cust.LoanID)
+	assert.Equal(t, "12", *cust.LoanID)
+
+	assert.NoError(t, c.ReactivateCustomer(context.Background(), "3"))
+}
+
+func TestClientImportCustomersSendsRawBody(t *testing.T) {
+	const upload = "name,address,external_ref\nJane,1 Main St,crm-1\n"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/customers/import", r.URL.Path)
+		assert.Equal(t, "text/csv", r.Header.Get("Content-Type"))
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, upload, string(body))
+		w.Write([]byte(`{"total":1,"created":1,"results":[{"line":2,"externalRef":"crm-1","status":"created","customerId":"5"}]}`))
+	}))
+	defer srv.Close()
+
+	report, err := New(srv.URL).ImportCustomers(context.Background(), "text/csv", strings.NewReader(upload))
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Created)
+	require.Len(t, report.Results, 1)
+	assert.Equal(t, "created", report.Results[0].Status)
+}
+
+func TestClientAPIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":{"message":"Resource not found."}}`))
+	}))
+	defer srv.Close()
+
+	_, err := New(srv.URL).GetLoan(context.Background(), "99", "schedule")
+
+	var apiErr *APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	assert.Equal(t, "Resource not found.", apiErr.Message)
+}
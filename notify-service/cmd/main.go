@@ -6,21 +6,30 @@ import (
 	"log"
 	"log/slog"
 	"net/http"
+	"notify-service/internal/api"
 	"notify-service/internal/config"
+	"notify-service/internal/domain/customer"
+	"notify-service/internal/domain/notification"
+	"notify-service/internal/domain/retry"
 	event "notify-service/internal/event/customer"
+	"notify-service/internal/i18n"
 	"notify-service/internal/infrastructure/database/postgres"
+	"notify-service/internal/infrastructure/errorreport"
 	"notify-service/internal/infrastructure/logging"
+	"notify-service/internal/infrastructure/sender"
+	"notify-service/internal/infrastructure/topology"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
+	_ "time/tzdata" // notifications.timezone must resolve in minimal images
 
 	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
 func main() {
-	cfg, logger := initializeConfigAndLogger()
+	cfg, logger, reporter := initializeConfigAndLogger()
 	ctx, cancel := setupSignalHandling()
 	defer cancel()
 
@@ -32,12 +41,31 @@ func main() {
 	defer closeRabbitMQ(rabbitConn, logger)
 
 	customerRepo := postgres.NewCustomerRepository(dbpool, logger)
-	eventHandler := event.NewCustomerEventHandler(customerRepo, logger)
-
-	logger.Info("Setting up Prometheus metrics endpoint", "path", "/metrics")
-	http.Handle("/metrics", promhttp.Handler())
+	notificationService := setupNotifications(cfg.Notifications, dbpool, customerRepo, customerRepo, logger)
+	var (
+		loanNotices    *event.LoanNotifier
+		contactNotices *event.ContactNotifier
+	)
+	if cfg.Notifications.Enabled {
+		catalog := loadCatalog(cfg.Notifications.CatalogDir, logger)
+		loanNotices = event.NewLoanNotifier(customerRepo, notificationService, cfg.Notifications.Channel)
+		loanNotices.UsePreferences(customerRepo)
+		loanNotices.UseCatalog(catalog)
+		loanNotices.UseChannels(notificationChannels(cfg.Notifications))
+		contactNotices = event.NewContactNotifier(notificationService, logger)
+		contactNotices.UsePreferences(customerRepo)
+		contactNotices.UseCatalog(catalog)
+	}
+	eventHandler, retryScheduler := setupEventHandler(cfg, dbpool, customerRepo, logger)
+	eventHandler.HandleLoanEvents(event.NewLoanEventHandler(postgres.NewLoanRepository(dbpool, logger), loanNotices, logger))
+	eventHandler.HandlePreferences(customerRepo)
+	eventHandler.HandleContacts(customerRepo, contactNotices)
+	if reporter != nil {
+		eventHandler.ReportErrors(reporter)
+	}
 
-	server := &http.Server{Addr: ":8090"}
+	logger.Info("Setting up HTTP endpoints", "metrics", "/metrics", "notifications", "/notifications")
+	server := &http.Server{Addr: ":8090", Handler: api.NewRouter(notificationService, cfg.Server.Auth, logger)}
 	go func() {
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			logger.Error("Failed to start HTTP server", slog.Any("error", err))
@@ -47,6 +75,17 @@ func main() {
 
 	consumer := setupConsumer(rabbitConn, cfg, eventHandler, logger)
 	go startConsumer(ctx, consumer, logger)
+	if retryScheduler != nil {
+		retryScheduler.Start(ctx)
+		defer retryScheduler.Stop()
+	}
+	dispatcher := notification.NewDispatcher(notificationService, notification.DispatcherConfig{
+		Interval:  cfg.Notifications.Deferred.Interval,
+		BatchSize: cfg.Notifications.Deferred.BatchSize,
+		Lease:     cfg.Notifications.Deferred.Lease,
+	}, logger)
+	dispatcher.Start(ctx)
+	defer dispatcher.Stop()
 
 	waitForShutdownSignal(ctx, consumer, logger)
 
@@ -57,14 +96,23 @@ func main() {
 	logger.Info("HTTP server shut down gracefully.")
 }
 
-func initializeConfigAndLogger() (*config.Config, *slog.Logger) {
+// initializeConfigAndLogger also returns the Sentry reporter, nil when no
+// DSN is configured, which the logger already reports error records to.
+func initializeConfigAndLogger() (*config.Config, *slog.Logger, errorreport.Reporter) {
 	cfg, err := config.LoadConfig(".")
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
-	logger := logging.NewLogger(cfg.Logger)
+	reporter, reporterErr := errorreport.New(cfg.ErrorReporting, nil)
+	logger := logging.NewLogger(cfg.Logger, reporter)
 	logger.Info("Configuration loaded successfully")
-	return cfg, logger
+	switch {
+	case reporterErr != nil:
+		logger.Error("Failed to configure error reporting, errors are only logged", "error", reporterErr)
+	case reporter != nil:
+		logger.Info("Reporting errors to Sentry", "environment", cfg.ErrorReporting.Environment)
+	}
+	return cfg, logger, reporter
 }
 
 func setupSignalHandling() (context.Context, context.CancelFunc) {
@@ -88,6 +136,15 @@ func setupDatabase(ctx context.Context, cfg *config.Config, logger *slog.Logger)
 	return dbpool
 }
 
+func loadCatalog(dir string, logger *slog.Logger) *i18n.Catalog {
+	catalog, err := i18n.Load(dir)
+	if err != nil {
+		logger.Error("Failed to load message catalogs", slog.String("dir", dir), slog.Any("error", err))
+		os.Exit(1)
+	}
+	return catalog
+}
+
 func closeDatabase(dbpool *pgxpool.Pool, logger *slog.Logger) {
 	logger.Info("Closing database connection pool...")
 	dbpool.Close()
@@ -99,6 +156,11 @@ func setupRabbitMQ(cfg *config.Config, logger *slog.Logger) *amqp.Connection {
 		logger.Error("Failed to connect to RabbitMQ", slog.Any("error", err))
 		os.Exit(1)
 	}
+	if err := topology.Declare(rabbitConn, cfg.RabbitMQ.Topology, logger); err != nil {
+		logger.Error("Failed to declare RabbitMQ topology", slog.Any("error", err))
+		_ = rabbitConn.Close()
+		os.Exit(1)
+	}
 	return rabbitConn
 }
 
@@ -109,13 +171,160 @@ func closeRabbitMQ(rabbitConn *amqp.Connection, logger *slog.Logger) {
 	}
 }
 
+// setupNotifications builds the notification log and its senders. The log
+// channel is always there; sms and email exist once their providers are
+// configured. Every message is checked against the customer's preferences
+// and addressed to their verified contact on its channel when they have one.
+func setupNotifications(cfg config.NotificationsConfig, dbpool *pgxpool.Pool, prefs customer.PreferencesRepository, contacts customer.ContactsRepository, logger *slog.Logger) notification.Service {
+	rules, err := notificationRules(cfg)
+	if err != nil {
+		logger.Error("Invalid notification rules", slog.Any("error", err))
+		os.Exit(1)
+	}
+	senders, err := notificationSenders(cfg, logger)
+	if err != nil {
+		logger.Error("Invalid notification providers", slog.Any("error", err))
+		os.Exit(1)
+	}
+	return notification.NewService(postgres.NewNotificationRepository(dbpool, logger), senders, rules, prefs, contacts, logger)
+}
+
+// notificationSenders puts each configured channel behind a failover sender
+// over its providers, in the order they are listed.
+func notificationSenders(cfg config.NotificationsConfig, logger *slog.Logger) (map[string]notification.Sender, error) {
+	senders := map[string]notification.Sender{
+		sender.ChannelLog: sender.NewLogSender(logger),
+	}
+	failover := sender.FailoverConfig{
+		FailureThreshold: cfg.Failover.FailureThreshold,
+		Cooldown:         cfg.Failover.Cooldown,
+	}
+	for channel, c := range cfg.Channels {
+		if channel != sender.ChannelSMS && channel != sender.ChannelEmail {
+			return nil, fmt.Errorf("unknown notifications channel %q", channel)
+		}
+		if len(c.Providers) == 0 {
+			return nil, fmt.Errorf("notifications channel %q has no providers", channel)
+		}
+		providers := make([]sender.Provider, 0, len(c.Providers))
+		for _, name := range c.Providers {
+			p, err := notificationProvider(channel, name, cfg.Providers)
+			if err != nil {
+				return nil, fmt.Errorf("notifications channel %q: %w", channel, err)
+			}
+			providers = append(providers, p)
+		}
+		senders[channel] = sender.NewFailover(channel, providers, failover, logger)
+	}
+	return senders, nil
+}
+
+// notificationChannels lists the channels notificationSenders gives a
+// sender: log and the configured ones.
+func notificationChannels(cfg config.NotificationsConfig) []string {
+	channels := []string{sender.ChannelLog}
+	for channel := range cfg.Channels {
+		channels = append(channels, channel)
+	}
+	return channels
+}
+
+func notificationProvider(channel, name string, cfg config.ProvidersConfig) (sender.Provider, error) {
+	awsConfig := func(c config.AWSConfig) sender.AWSConfig {
+		return sender.AWSConfig{
+			Region:          c.Region,
+			AccessKeyID:     c.AccessKeyID,
+			SecretAccessKey: c.SecretAccessKey,
+			Endpoint:        c.Endpoint,
+			Timeout:         cfg.Timeout,
+		}
+	}
+	switch {
+	case channel == sender.ChannelSMS && name == sender.ProviderTwilio:
+		return sender.NewTwilioSender(sender.TwilioConfig{
+			AccountSID: cfg.Twilio.AccountSID,
+			AuthToken:  cfg.Twilio.AuthToken,
+			From:       cfg.Twilio.From,
+			Timeout:    cfg.Timeout,
+		})
+	case channel == sender.ChannelSMS && name == sender.ProviderSNS:
+		return sender.NewSNSSender(awsConfig(cfg.SNS.AWSConfig), cfg.SNS.SenderID)
+	case channel == sender.ChannelEmail && name == sender.ProviderSES:
+		return sender.NewSESSender(awsConfig(cfg.SES.AWSConfig), cfg.SES.From)
+	case channel == sender.ChannelEmail && name == sender.ProviderSMTP:
+		return sender.NewSMTPSender(sender.SMTPConfig{
+			Host:     cfg.SMTP.Host,
+			Port:     cfg.SMTP.Port,
+			Username: cfg.SMTP.Username,
+			Password: cfg.SMTP.Password,
+			From:     cfg.SMTP.From,
+			Timeout:  cfg.Timeout,
+		})
+	}
+	return nil, fmt.Errorf("provider %q cannot send %s", name, channel)
+}
+
+func notificationRules(cfg config.NotificationsConfig) (notification.Rules, error) {
+	location, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		return notification.Rules{}, fmt.Errorf("invalid notifications.timezone %q: %w", cfg.Timezone, err)
+	}
+
+	rules := notification.Rules{
+		Location:   location,
+		QuietHours: make(map[string]notification.QuietHours, len(cfg.QuietHours)),
+		DailyCap:   cfg.DailyCap,
+	}
+	for channel, q := range cfg.QuietHours {
+		start, err := notification.ParseClock(q.Start)
+		if err != nil {
+			return notification.Rules{}, fmt.Errorf("quiet hours of %s: %w", channel, err)
+		}
+		end, err := notification.ParseClock(q.End)
+		if err != nil {
+			return notification.Rules{}, fmt.Errorf("quiet hours of %s: %w", channel, err)
+		}
+		rules.QuietHours[channel] = notification.QuietHours{Start: start, End: end}
+	}
+	return rules, nil
+}
+
+// setupEventHandler wires the persistent retry store unless it is disabled,
+// in which case failed deliveries are dropped and no scheduler runs.
+func setupEventHandler(cfg *config.Config, dbpool *pgxpool.Pool, customerRepo customer.CustomerRepository, logger *slog.Logger) (*event.CustomerEventHandler, *event.RetryScheduler) {
+	processedRepo := postgres.NewProcessedEventRepository(dbpool, logger)
+	policy := retry.Policy{
+		MaxAttempts: cfg.Retry.MaxAttempts,
+		BaseDelay:   cfg.Retry.BaseDelay,
+		MaxDelay:    cfg.Retry.MaxDelay,
+	}
+	if !cfg.Retry.Enabled {
+		logger.Warn("Retry store disabled, failed deliveries will be dropped")
+		return event.NewCustomerEventHandler(customerRepo, processedRepo, nil, policy, logger), nil
+	}
+
+	retryRepo := postgres.NewRetryRepository(dbpool, logger)
+	eventHandler := event.NewCustomerEventHandler(customerRepo, processedRepo, retryRepo, policy, logger)
+	scheduler := event.NewRetryScheduler(eventHandler.Process, retryRepo, event.RetrySchedulerConfig{
+		Interval:  cfg.Retry.Interval,
+		BatchSize: cfg.Retry.BatchSize,
+		Lease:     cfg.Retry.Lease,
+		Policy:    policy,
+	}, logger)
+	return eventHandler, scheduler
+}
+
 func setupConsumer(rabbitConn *amqp.Connection, cfg *config.Config, eventHandler *event.CustomerEventHandler, logger *slog.Logger) *event.Consumer {
 	consumer, err := event.NewConsumer(
 		rabbitConn,
-		cfg.RabbitMQ.ExchangeName,
-		cfg.RabbitMQ.QueueName,
+		cfg.RabbitMQ.Queues,
 		cfg.RabbitMQ.ConsumerTag,
 		eventHandler.HandleDelivery,
+		event.ConsumerConfig{
+			PrefetchCount: cfg.RabbitMQ.PrefetchCount,
+			Workers:       cfg.RabbitMQ.Workers,
+			DrainTimeout:  cfg.RabbitMQ.DrainTimeout,
+		},
 		logger,
 	)
 	if err != nil {
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"notify-service/internal/domain/notification"
+	"strconv"
+	"time"
+)
+
+type NotificationResponse struct {
+	ID           int64      `json:"id"`
+	CustomerID   int64      `json:"customerId"`
+	Event        string     `json:"event"`
+	Channel      string     `json:"channel"`
+	Recipient    string     `json:"recipient"`
+	Subject      string     `json:"subject,omitempty"`
+	Status       string     `json:"status"`
+	Error        string     `json:"error,omitempty"`
+	SentAt       *time.Time `json:"sentAt"`
+	DeliverAfter *time.Time `json:"deliverAfter,omitempty"`
+	CreatedAt    time.Time  `json:"createdAt"`
+}
+
+type NotificationHandler struct {
+	service notification.Service
+	logger  *slog.Logger
+}
+
+func NewNotificationHandler(s notification.Service, l *slog.Logger) *NotificationHandler {
+	if s == nil {
+		panic("notification service cannot be nil")
+	}
+	if l == nil {
+		panic("logger cannot be nil")
+	}
+	return &NotificationHandler{
+		service: s,
+		logger:  l.With("component", "NotificationHandler"),
+	}
+}
+
+// ListNotifications handles GET /notifications?customer_id=&limit=. It
+// answers with the customer's notifications, newest first.
+func (h *NotificationHandler) ListNotifications(w http.ResponseWriter, r *http.Request) {
+	customerID, err := strconv.ParseInt(r.URL.Query().Get("customer_id"), 10, 64)
+	if err != nil || customerID <= 0 {
+		respondError(w, http.StatusBadRequest, "customer_id must be a positive integer")
+		return
+	}
+
+	limit := notification.DefaultListLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			respondError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+	}
+
+	notifications, err := h.service.ListByCustomer(r.Context(), customerID, limit)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to list notifications", slog.Int64("customerID", customerID), slog.Any("error", err))
+		respondError(w, http.StatusInternalServerError, "An internal server error occurred")
+		return
+	}
+
+	resp := make([]NotificationResponse, len(notifications))
+	for i, n := range notifications {
+		resp[i] = NotificationResponse{
+			ID:           n.ID,
+			CustomerID:   n.CustomerID,
+			Event:        n.Event,
+			Channel:      n.Channel,
+			Recipient:    n.Recipient,
+			Subject:      n.Subject,
+			Status:       string(n.Status),
+			Error:        n.Error,
+			SentAt:       n.SentAt,
+			DeliverAfter: n.DeliverAfter,
+			CreatedAt:    n.CreatedAt,
+		}
+	}
+	respondJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"notify-service/internal/domain/notification"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockNotificationService struct {
+	mock.Mock
+}
+
+func (m *mockNotificationService) Notify(ctx context.Context, customerID int64, event string, msg notification.Message) (*notification.Notification, error) {
+	args := m.Called(ctx, customerID, event, msg)
+	n, _ := args.Get(0).(*notification.Notification)
+	return n, args.Error(1)
+}
+
+func (m *mockNotificationService) DispatchDeferred(ctx context.Context, lease time.Duration, limit int) (int, error) {
+	args := m.Called(ctx, lease, limit)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *mockNotificationService) ListByCustomer(ctx context.Context, customerID int64, limit int) ([]notification.Notification, error) {
+	args := m.Called(ctx, customerID, limit)
+	notifications, _ := args.Get(0).([]notification.Notification)
+	return notifications, args.Error(1)
+}
+
+func TestListNotifications(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sentAt := time.Date(2025, 4, 1, 9, 0, 0, 0, time.UTC)
+
+	t.Run("returns notifications of the customer", func(t *testing.T) {
+		svc := new(mockNotificationService)
+		svc.On("ListByCustomer", mock.Anything, int64(7), notification.DefaultListLimit).Return([]notification.Notification{
+			{ID: 2, CustomerID: 7, Event: notification.EventDelinquencyNotice, Channel: "log", Status: notification.StatusFailed, Error: "gateway timeout"},
+			{ID: 1, CustomerID: 7, Event: notification.EventDelinquencyNotice, Channel: "log", Status: notification.StatusSent, SentAt: &sentAt},
+		}, nil)
+		rec := httptest.NewRecorder()
+
+		NewNotificationHandler(svc, logger).ListNotifications(rec, httptest.NewRequest(http.MethodGet, "/notifications?customer_id=7", nil))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		var body []NotificationResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+		require.Len(t, body, 2)
+		assert.Equal(t, "FAILED", body[0].Status)
+		assert.Equal(t, "gateway timeout", body[0].Error)
+		assert.Nil(t, body[0].SentAt)
+		assert.Equal(t, sentAt, *body[1].SentAt)
+	})
+
+	t.Run("passes limit", func(t *testing.T) {
+		svc := new(mockNotificationService)
+		svc.On("ListByCustomer", mock.Anything, int64(7), 5).Return([]notification.Notification{}, nil)
+		rec := httptest.NewRecorder()
+
+		NewNotificationHandler(svc, logger).ListNotifications(rec, httptest.NewRequest(http.MethodGet, "/notifications?customer_id=7&limit=5", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `[]`, rec.Body.String())
+		svc.AssertExpectations(t)
+	})
+
+	for _, target := range []string{"/notifications", "/notifications?customer_id=abc", "/notifications?customer_id=7&limit=-1"} {
+		t.Run("rejects "+target, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			NewNotificationHandler(new(mockNotificationService), logger).ListNotifications(rec, httptest.NewRequest(http.MethodGet, target, nil))
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+		})
+	}
+
+	t.Run("service error", func(t *testing.T) {
+		svc := new(mockNotificationService)
+		svc.On("ListByCustomer", mock.Anything, int64(7), notification.DefaultListLimit).Return(nil, errors.New("db down"))
+		rec := httptest.NewRecorder()
+
+		NewNotificationHandler(svc, logger).ListNotifications(rec, httptest.NewRequest(http.MethodGet, "/notifications?customer_id=7", nil))
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
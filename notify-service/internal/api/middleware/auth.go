@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"notify-service/internal/config"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ScopeCustomer marks self-service tokens issued by billing-engine. They share
+// the signing secret but must not read other customers' notifications.
+const ScopeCustomer = "customer"
+
+type claimsContextKey struct{}
+
+func ClaimsFromContext(ctx context.Context) (jwt.MapClaims, bool) {
+	claims, ok := ctx.Value(claimsContextKey{}).(jwt.MapClaims)
+	return claims, ok
+}
+
+func AuthMiddleware(cfg config.AuthConfig, logger *slog.Logger) func(http.Handler) http.Handler {
+	if !cfg.Enabled {
+		return func(next http.Handler) http.Handler {
+			return next
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := validateJWT(r, cfg.JWTSecret, logger)
+			if !ok {
+				http.Error(w, `{"error":{"message":"Unauthorized"}}`, http.StatusUnauthorized)
+				return
+			}
+			ctx := context.WithValue(r.Context(), claimsContextKey{}, claims)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func StaffOnly(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if claims, ok := ClaimsFromContext(r.Context()); ok {
+				if scope, _ := claims["scope"].(string); scope == ScopeCustomer {
+					logger.Warn("AuthMiddleware: Customer scoped token used on staff route", "path", r.URL.Path)
+					http.Error(w, `{"error":{"message":"Forbidden"}}`, http.StatusForbidden)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func validateJWT(r *http.Request, secret string, logger *slog.Logger) (jwt.MapClaims, bool) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		logger.Warn("AuthMiddleware: Missing Authorization header")
+		return nil, false
+	}
+
+	parts := strings.SplitN(authHeader, " ", 2)
+	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+		logger.Warn("AuthMiddleware: Invalid Authorization header format")
+		return nil, false
+	}
+
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(parts[1], claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			logger.Warn("AuthMiddleware: Unexpected signing method")
+			return nil, http.ErrAbortHandler
+		}
+		return []byte(secret), nil
+	})
+	if err != nil || !token.Valid {
+		logger.Warn("AuthMiddleware: Invalid token", "error", err)
+		return nil, false
+	}
+	return claims, true
+}
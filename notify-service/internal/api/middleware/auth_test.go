@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"notify-service/internal/config"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func signTestToken(t *testing.T, secret string, claims jwt.MapClaims) string {
+	t.Helper()
+	tokenString, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return tokenString
+}
+
+func TestStaffAuth(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	secret := "testsecret"
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	chain := AuthMiddleware(config.AuthConfig{Enabled: true, JWTSecret: secret}, logger)(StaffOnly(logger)(next))
+
+	tests := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{name: "missing header", want: http.StatusUnauthorized},
+		{name: "invalid token", header: "Bearer invalidtoken", want: http.StatusUnauthorized},
+		{name: "wrong secret", header: "Bearer " + signTestToken(t, "other", jwt.MapClaims{"username": "ops"}), want: http.StatusUnauthorized},
+		{name: "customer scoped token", header: "Bearer " + signTestToken(t, secret, jwt.MapClaims{"sub": "42", "scope": ScopeCustomer}), want: http.StatusForbidden},
+		{name: "staff token", header: "Bearer " + signTestToken(t, secret, jwt.MapClaims{"username": "ops"}), want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/notifications?customer_id=1", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+
+			chain.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("expected status %d, got %d", tt.want, rec.Code)
+			}
+		})
+	}
+
+	t.Run("disabled auth lets requests through", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		AuthMiddleware(config.AuthConfig{Enabled: false}, logger)(next).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+	})
+}
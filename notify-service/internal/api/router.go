@@ -0,0 +1,27 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"notify-service/internal/api/handler"
+	"notify-service/internal/api/middleware"
+	"notify-service/internal/config"
+	"notify-service/internal/domain/notification"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// NewRouter serves the Prometheus metrics and the support API. Metrics stay
+// unauthenticated so the scraper keeps working.
+func NewRouter(notifications notification.Service, cfg config.AuthConfig, logger *slog.Logger) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", promhttp.Handler())
+
+	staff := func(h http.HandlerFunc) http.Handler {
+		return middleware.AuthMiddleware(cfg, logger)(middleware.StaffOnly(logger)(h))
+	}
+	notificationHandler := handler.NewNotificationHandler(notifications, logger)
+	mux.Handle("GET /notifications", staff(notificationHandler.ListNotifications))
+
+	return mux
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"events"
 	"log"
 	"strings"
 	"time"
@@ -14,6 +15,11 @@ type Config struct {
 	Logger   LoggerConfig   `mapstructure:"logger"`
 	Metrics  MetricsConfig  `mapstructure:"metrics"`
 	RabbitMQ RabbitMQConfig `mapstructure:"rabbitmq"`
+	Retry    RetryConfig    `mapstructure:"retry"`
+
+	ErrorReporting ErrorReportingConfig `mapstructure:"errorReporting"`
+
+	Notifications NotificationsConfig `mapstructure:"notifications"`
 }
 
 type ServerConfig struct {
@@ -45,19 +51,193 @@ type LoggerConfig struct {
 	Encoding string `mapstructure:"encoding"`
 }
 
+// ErrorReportingConfig sends error logs and failed deliveries to the Sentry
+// project DSN names; without a DSN nothing is reported. Reports are tagged
+// with Environment and Release, the latter defaulting to the revision the
+// binary was built from.
+type ErrorReportingConfig struct {
+	DSN         string        `mapstructure:"dsn"`
+	Environment string        `mapstructure:"environment"`
+	Release     string        `mapstructure:"release"`
+	Timeout     time.Duration `mapstructure:"timeout"`
+}
+
 type MetricsConfig struct {
 	Port int    `mapstructure:"port"`
 	Path string `mapstructure:"path"`
 }
 
+// RabbitMQConfig also sizes the consumer: PrefetchCount unacknowledged
+// deliveries are handled by Workers goroutines, and shutdown waits up to
+// DrainTimeout for the ones in flight. Queues are consumed after Topology
+// is declared; without a topology in the config file the default layout
+// from DefaultTopology is used.
 type RabbitMQConfig struct {
-	Host         string `mapstructure:"host"`
-	Port         int    `mapstructure:"port"`
-	Username     string `mapstructure:"username"`
-	Password     string `mapstructure:"password"`
-	QueueName    string `mapstructure:"queueName"`
-	ExchangeName string `mapstructure:"exchangeName"`
-	ConsumerTag  string `mapstructure:"consumerTag"`
+	Host          string         `mapstructure:"host"`
+	Port          int            `mapstructure:"port"`
+	Username      string         `mapstructure:"username"`
+	Password      string         `mapstructure:"password"`
+	Queues        []string       `mapstructure:"queues"`
+	ExchangeName  string         `mapstructure:"exchangeName"`
+	ConsumerTag   string         `mapstructure:"consumerTag"`
+	PrefetchCount int            `mapstructure:"prefetchCount"`
+	Workers       int            `mapstructure:"workers"`
+	DrainTimeout  time.Duration  `mapstructure:"drainTimeout"`
+	Topology      TopologyConfig `mapstructure:"topology"`
+}
+
+// TopologyConfig lists the exchanges and queues that billing-engine and
+// notify-service both declare at startup. Declaring is idempotent, so the
+// service that starts first creates them and messages published before
+// notify-service first runs are queued rather than dropped.
+type TopologyConfig struct {
+	Exchanges []ExchangeConfig `mapstructure:"exchanges"`
+	Queues    []QueueConfig    `mapstructure:"queues"`
+}
+
+// ExchangeConfig declares a durable exchange. Type defaults to topic.
+type ExchangeConfig struct {
+	Name string `mapstructure:"name"`
+	Type string `mapstructure:"type"`
+}
+
+// QueueConfig declares a durable queue and its bindings. Messages the queue
+// rejects or lets expire go to DeadLetterExchange, under
+// DeadLetterRoutingKey when it is set and their own routing key otherwise.
+// A MessageTTL together with a dead-letter exchange makes a retry queue:
+// messages wait out the TTL and are then routed again.
+type QueueConfig struct {
+	Name                 string          `mapstructure:"name"`
+	DeadLetterExchange   string          `mapstructure:"deadLetterExchange"`
+	DeadLetterRoutingKey string          `mapstructure:"deadLetterRoutingKey"`
+	MessageTTL           time.Duration   `mapstructure:"messageTTL"`
+	Bindings             []BindingConfig `mapstructure:"bindings"`
+}
+
+type BindingConfig struct {
+	Exchange    string   `mapstructure:"exchange"`
+	RoutingKeys []string `mapstructure:"routingKeys"`
+}
+
+// DefaultTopology is the layout of notify-service's customer and loan
+// queues on exchange. Each queue dead-letters into its own queue on the
+// exchange's ".dlx" companion. billing-engine carries the same default, and
+// both take the routing keys from the shared events module.
+func DefaultTopology(exchange string) TopologyConfig {
+	dlx := exchange + ".dlx"
+	customerKeys := []string{events.RoutingKeyCustomerCreated, events.RoutingKeyCustomerUpdated, events.RoutingKeyCustomerDelinquencyChanged, events.RoutingKeyCustomerPreferencesChanged,
+		events.RoutingKeyCustomerContactsChanged, events.RoutingKeyCustomerContactVerificationRequested}
+	loanKeys := []string{events.RoutingKeyLoanCreated, events.RoutingKeyLoanPaymentReceived, events.RoutingKeyLoanDelinquent, events.RoutingKeyLoanPaidOff, events.RoutingKeyLoanReminderDue}
+	return TopologyConfig{
+		Exchanges: []ExchangeConfig{{Name: exchange, Type: "topic"}, {Name: dlx, Type: "topic"}},
+		Queues: []QueueConfig{
+			{Name: "notify-service.customer", DeadLetterExchange: dlx, Bindings: []BindingConfig{{Exchange: exchange, RoutingKeys: customerKeys}}},
+			{Name: "notify-service.loan", DeadLetterExchange: dlx, Bindings: []BindingConfig{{Exchange: exchange, RoutingKeys: loanKeys}}},
+			{Name: "notify-service.customer.dlq", Bindings: []BindingConfig{{Exchange: dlx, RoutingKeys: customerKeys}}},
+			{Name: "notify-service.loan.dlq", Bindings: []BindingConfig{{Exchange: dlx, RoutingKeys: loanKeys}}},
+		},
+	}
+}
+
+// RetryConfig controls the notify_retries store. Failed deliveries are
+// retried with a delay that doubles from BaseDelay up to MaxDelay until
+// MaxAttempts is reached.
+type RetryConfig struct {
+	Enabled     bool          `mapstructure:"enabled"`
+	Interval    time.Duration `mapstructure:"interval"`
+	BatchSize   int           `mapstructure:"batchSize"`
+	Lease       time.Duration `mapstructure:"lease"`
+	MaxAttempts int           `mapstructure:"maxAttempts"`
+	BaseDelay   time.Duration `mapstructure:"baseDelay"`
+	MaxDelay    time.Duration `mapstructure:"maxDelay"`
+}
+
+// NotificationsConfig controls the customer notices sent from events. Channel
+// names the default sender, also used for reminders whose channel has none. Quiet hours are keyed by
+// channel and read in Timezone; messages that fall into them, or that exceed
+// DailyCap for the customer's local day, are deferred. A DailyCap of zero
+// disables the cap. Channels adds the sms and email channels next to log,
+// each delivered by its providers in order of preference. CatalogDir holds
+// <locale>.json message catalogs that override or extend the built-in ones.
+type NotificationsConfig struct {
+	Enabled    bool                        `mapstructure:"enabled"`
+	Channel    string                      `mapstructure:"channel"`
+	Timezone   string                      `mapstructure:"timezone"`
+	DailyCap   int                         `mapstructure:"dailyCap"`
+	QuietHours map[string]QuietHoursConfig `mapstructure:"quietHours"`
+	Deferred   DeferredConfig              `mapstructure:"deferred"`
+	Channels   map[string]ChannelConfig    `mapstructure:"channels"`
+	Failover   FailoverConfig              `mapstructure:"failover"`
+	Providers  ProvidersConfig             `mapstructure:"providers"`
+	CatalogDir string                      `mapstructure:"catalogDir"`
+}
+
+// QuietHoursConfig holds wall clock times such as "21:00".
+type QuietHoursConfig struct {
+	Start string `mapstructure:"start"`
+	End   string `mapstructure:"end"`
+}
+
+type DeferredConfig struct {
+	Interval  time.Duration `mapstructure:"interval"`
+	BatchSize int           `mapstructure:"batchSize"`
+	Lease     time.Duration `mapstructure:"lease"`
+}
+
+// ChannelConfig lists provider names, primary first, such as
+// ["twilio", "sns"] for sms or ["ses", "smtp"] for email.
+type ChannelConfig struct {
+	Providers []string `mapstructure:"providers"`
+}
+
+// FailoverConfig takes a provider out of rotation for Cooldown after
+// FailureThreshold failed deliveries in a row.
+type FailoverConfig struct {
+	FailureThreshold int           `mapstructure:"failureThreshold"`
+	Cooldown         time.Duration `mapstructure:"cooldown"`
+}
+
+// ProvidersConfig holds the credentials of every provider a channel may
+// name. Timeout bounds each call to a provider.
+type ProvidersConfig struct {
+	Timeout time.Duration `mapstructure:"timeout"`
+	Twilio  TwilioConfig  `mapstructure:"twilio"`
+	SNS     SNSConfig     `mapstructure:"sns"`
+	SES     SESConfig     `mapstructure:"ses"`
+	SMTP    SMTPConfig    `mapstructure:"smtp"`
+}
+
+type TwilioConfig struct {
+	AccountSID string `mapstructure:"accountSid"`
+	AuthToken  string `mapstructure:"authToken"`
+	From       string `mapstructure:"from"`
+}
+
+// AWSConfig is shared by SNS and SES. Endpoint replaces the regional
+// endpoint, for example with a LocalStack URL.
+type AWSConfig struct {
+	Region          string `mapstructure:"region"`
+	AccessKeyID     string `mapstructure:"accessKeyId"`
+	SecretAccessKey string `mapstructure:"secretAccessKey"`
+	Endpoint        string `mapstructure:"endpoint"`
+}
+
+type SNSConfig struct {
+	AWSConfig `mapstructure:",squash"`
+	SenderID  string `mapstructure:"senderId"`
+}
+
+type SESConfig struct {
+	AWSConfig `mapstructure:",squash"`
+	From      string `mapstructure:"from"`
+}
+
+type SMTPConfig struct {
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	From     string `mapstructure:"from"`
 }
 
 func LoadConfig(path string) (*Config, error) {
@@ -81,14 +261,53 @@ func LoadConfig(path string) (*Config, error) {
 	viper.SetDefault("logger.encoding", "json")
 	viper.SetDefault("metrics.port", 9090)
 	viper.SetDefault("metrics.path", "/metrics")
+	viper.SetDefault("errorReporting.dsn", "")
+	viper.SetDefault("errorReporting.environment", "production")
+	viper.SetDefault("errorReporting.release", "")
+	viper.SetDefault("errorReporting.timeout", 5*time.Second)
 	viper.SetDefault("server.auth.JWTSecret", "")
 	viper.SetDefault("rabbitmq.host", "localhost")
 	viper.SetDefault("rabbitmq.port", 5672)
 	viper.SetDefault("rabbitmq.username", "guest")
 	viper.SetDefault("rabbitmq.password", "guest")
-	viper.SetDefault("rabbitmq.queueName", "notify-service")
+	viper.SetDefault("rabbitmq.queues", []string{"notify-service.customer", "notify-service.loan"})
 	viper.SetDefault("rabbitmq.exchangeName", "billing-engine")
 	viper.SetDefault("rabbitmq.consumerTag", "notify-service-consumer")
+	viper.SetDefault("rabbitmq.prefetchCount", 10)
+	viper.SetDefault("rabbitmq.workers", 4)
+	viper.SetDefault("rabbitmq.drainTimeout", 30*time.Second)
+	viper.SetDefault("retry.enabled", true)
+	viper.SetDefault("retry.interval", 15*time.Second)
+	viper.SetDefault("retry.batchSize", 50)
+	viper.SetDefault("retry.lease", 5*time.Minute)
+	viper.SetDefault("retry.maxAttempts", 10)
+	viper.SetDefault("retry.baseDelay", 30*time.Second)
+	viper.SetDefault("retry.maxDelay", time.Hour)
+	viper.SetDefault("notifications.enabled", true)
+	viper.SetDefault("notifications.channel", "log")
+	viper.SetDefault("notifications.timezone", "Local")
+	viper.SetDefault("notifications.dailyCap", 3)
+	viper.SetDefault("notifications.catalogDir", "")
+	viper.SetDefault("notifications.quietHours", map[string]any{
+		"sms": map[string]any{"start": "21:00", "end": "08:00"},
+	})
+	viper.SetDefault("notifications.deferred.interval", time.Minute)
+	viper.SetDefault("notifications.deferred.batchSize", 50)
+	viper.SetDefault("notifications.deferred.lease", 5*time.Minute)
+	viper.SetDefault("notifications.failover.failureThreshold", 3)
+	viper.SetDefault("notifications.failover.cooldown", time.Minute)
+	viper.SetDefault("notifications.providers.timeout", 10*time.Second)
+	// Empty credentials are declared so that environment variables such as
+	// NOTIFICATIONS_PROVIDERS_TWILIO_AUTHTOKEN can supply them.
+	for _, key := range []string{
+		"twilio.accountSid", "twilio.authToken", "twilio.from",
+		"sns.region", "sns.accessKeyId", "sns.secretAccessKey", "sns.endpoint", "sns.senderId",
+		"ses.region", "ses.accessKeyId", "ses.secretAccessKey", "ses.endpoint", "ses.from",
+		"smtp.host", "smtp.username", "smtp.password", "smtp.from",
+	} {
+		viper.SetDefault("notifications.providers."+key, "")
+	}
+	viper.SetDefault("notifications.providers.smtp.port", 587)
 
 	if err := viper.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
@@ -102,6 +321,9 @@ func LoadConfig(path string) (*Config, error) {
 	if err := viper.Unmarshal(&cfg); err != nil {
 		return nil, err
 	}
+	if len(cfg.RabbitMQ.Topology.Exchanges) == 0 && len(cfg.RabbitMQ.Topology.Queues) == 0 {
+		cfg.RabbitMQ.Topology = DefaultTopology(cfg.RabbitMQ.ExchangeName)
+	}
 
 	return &cfg, nil
 }
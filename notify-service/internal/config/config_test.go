@@ -5,7 +5,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestLoadConfig(t *testing.T) {
@@ -31,6 +33,46 @@ func TestLoadConfig(t *testing.T) {
 
 		assert.Equal(t, 9090, cfg.Metrics.Port)
 		assert.Equal(t, "/metrics", cfg.Metrics.Path)
+
+		assert.Empty(t, cfg.ErrorReporting.DSN)
+		assert.Equal(t, "production", cfg.ErrorReporting.Environment)
+		assert.Equal(t, 5*time.Second, cfg.ErrorReporting.Timeout)
+
+		assert.Equal(t, 10, cfg.RabbitMQ.PrefetchCount)
+		assert.Equal(t, 4, cfg.RabbitMQ.Workers)
+		assert.Equal(t, 30*time.Second, cfg.RabbitMQ.DrainTimeout)
+		assert.Equal(t, []string{"notify-service.customer", "notify-service.loan"}, cfg.RabbitMQ.Queues)
+		assert.Equal(t, DefaultTopology(cfg.RabbitMQ.ExchangeName), cfg.RabbitMQ.Topology)
+
+		assert.True(t, cfg.Retry.Enabled)
+		assert.Equal(t, 15*time.Second, cfg.Retry.Interval)
+		assert.Equal(t, 50, cfg.Retry.BatchSize)
+		assert.Equal(t, 10, cfg.Retry.MaxAttempts)
+		assert.Equal(t, 30*time.Second, cfg.Retry.BaseDelay)
+		assert.Equal(t, time.Hour, cfg.Retry.MaxDelay)
+
+		assert.True(t, cfg.Notifications.Enabled)
+		assert.Equal(t, "log", cfg.Notifications.Channel)
+		assert.Equal(t, "Local", cfg.Notifications.Timezone)
+		assert.Equal(t, 3, cfg.Notifications.DailyCap)
+		assert.Equal(t, QuietHoursConfig{Start: "21:00", End: "08:00"}, cfg.Notifications.QuietHours["sms"])
+		assert.Equal(t, time.Minute, cfg.Notifications.Deferred.Interval)
+		assert.Equal(t, 50, cfg.Notifications.Deferred.BatchSize)
+		assert.Empty(t, cfg.Notifications.Channels)
+		assert.Equal(t, FailoverConfig{FailureThreshold: 3, Cooldown: time.Minute}, cfg.Notifications.Failover)
+		assert.Equal(t, 10*time.Second, cfg.Notifications.Providers.Timeout)
+		assert.Empty(t, cfg.Notifications.CatalogDir)
+		assert.Equal(t, 587, cfg.Notifications.Providers.SMTP.Port)
+	})
+
+	t.Run("Read provider credentials from the environment", func(t *testing.T) {
+		t.Setenv("NOTIFICATIONS_PROVIDERS_TWILIO_AUTHTOKEN", "token")
+		t.Setenv("NOTIFICATIONS_PROVIDERS_SES_REGION", "ap-southeast-1")
+
+		cfg, err := LoadConfig(".")
+		assert.NoError(t, err)
+		assert.Equal(t, "token", cfg.Notifications.Providers.Twilio.AuthToken)
+		assert.Equal(t, "ap-southeast-1", cfg.Notifications.Providers.SES.Region)
 	})
 
 	t.Run("Return error when config file is invalid", func(t *testing.T) {
@@ -42,3 +84,16 @@ func TestLoadConfig(t *testing.T) {
 		assert.NoError(t, err)
 	})
 }
+
+// config.yml spells out the default topology, and billing-engine's config
+// carries the same block; neither may drift from DefaultTopology.
+func TestConfigFileTopologyMatchesDefault(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	cfg, err := LoadConfig("../..")
+	require.NoError(t, err)
+	require.NotEmpty(t, viper.ConfigFileUsed())
+	assert.Equal(t, DefaultTopology(cfg.RabbitMQ.ExchangeName), cfg.RabbitMQ.Topology)
+	assert.Equal(t, []string{"notify-service.customer", "notify-service.loan"}, cfg.RabbitMQ.Queues)
+}
@@ -0,0 +1,52 @@
+package customer
+
+import "time"
+
+const (
+	ContactPhone = "phone"
+	ContactEmail = "email"
+)
+
+// contactChannels is the notification channel each contact type is sent on.
+var contactChannels = map[string]string{ContactPhone: "sms", ContactEmail: "email"}
+
+// ContactChannel returns the channel a contact of contactType is reached on,
+// or "" for a type no channel sends to.
+func ContactChannel(contactType string) string {
+	return contactChannels[contactType]
+}
+
+// Contact is a phone number or email address a customer added in
+// billing-engine. Only verified contacts are sent to.
+type Contact struct {
+	ContactID int64
+	Type      string
+	Value     string
+	Verified  bool
+	Primary   bool
+}
+
+// Contacts is this service's copy of every contact of a customer. A customer
+// without a stored copy has no contacts.
+type Contacts struct {
+	CustomerID int64
+	Contacts   []Contact
+	UpdatedAt  time.Time
+}
+
+// Recipient returns the verified contact to send to on channel, the primary
+// one when there is one. ok is false when the customer has none.
+func (c *Contacts) Recipient(channel string) (recipient string, ok bool) {
+	for _, contact := range c.Contacts {
+		if !contact.Verified || ContactChannel(contact.Type) != channel {
+			continue
+		}
+		if contact.Primary {
+			return contact.Value, true
+		}
+		if !ok {
+			recipient, ok = contact.Value, true
+		}
+	}
+	return recipient, ok
+}
@@ -0,0 +1,32 @@
+package customer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContactsRecipient(t *testing.T) {
+	contacts := &Contacts{CustomerID: 7, Contacts: []Contact{
+		{ContactID: 11, Type: ContactEmail, Value: "ayu@example.com", Verified: true},
+		{ContactID: 12, Type: ContactEmail, Value: "ayu@work.example.com", Verified: true, Primary: true},
+		{ContactID: 13, Type: ContactPhone, Value: "+6281234567890"},
+	}}
+
+	recipient, ok := contacts.Recipient("email")
+	assert.True(t, ok)
+	assert.Equal(t, "ayu@work.example.com", recipient, "primary first")
+
+	_, ok = contacts.Recipient("sms")
+	assert.False(t, ok, "an unverified phone is not sent to")
+
+	_, ok = (&Contacts{CustomerID: 7}).Recipient("log")
+	assert.False(t, ok)
+}
+
+func TestPreferencesOptedOutOfVerification(t *testing.T) {
+	prefs := &Preferences{MarketingOptOut: true, TransactionalOptOut: true}
+
+	assert.False(t, prefs.OptedOut(CategoryVerification))
+	assert.True(t, prefs.OptedOut(CategoryTransactional))
+}
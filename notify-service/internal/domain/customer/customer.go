@@ -11,4 +11,7 @@ type Customer struct {
 	LoanID       *int64
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
+	// Sequence is the number of the event the copy came from among the
+	// customer's events, zero when the event carried none.
+	Sequence int64
 }
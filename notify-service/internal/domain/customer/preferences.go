@@ -0,0 +1,36 @@
+package customer
+
+import "time"
+
+const (
+	CategoryTransactional = "transactional"
+	CategoryMarketing     = "marketing"
+
+	// CategoryVerification is a code the customer asked for to verify a
+	// contact. It cannot be opted out of.
+	CategoryVerification = "verification"
+)
+
+// Preferences is this service's copy of the communication preferences a
+// customer set in billing-engine. PreferredChannel and Language are empty
+// when the customer has no preference, and a customer who never set any has
+// the zero value.
+type Preferences struct {
+	CustomerID          int64
+	PreferredChannel    string
+	Language            string
+	MarketingOptOut     bool
+	TransactionalOptOut bool
+	UpdatedAt           time.Time
+}
+
+// OptedOut reports whether the customer declined messages of category.
+func (p *Preferences) OptedOut(category string) bool {
+	switch category {
+	case CategoryTransactional:
+		return p.TransactionalOptOut
+	case CategoryVerification:
+		return false
+	}
+	return p.MarketingOptOut
+}
@@ -18,5 +18,34 @@ var (
 )
 
 type CustomerRepository interface {
+	// Upsert ignores a copy older than the stored one: one with a sequence
+	// at or below the stored sequence, or when either has none, with an
+	// UpdatedAt that is not later.
 	Upsert(ctx context.Context, cust *Customer) error
+
+	// FindByID returns ErrNotFound until the customer has been replicated.
+	FindByID(ctx context.Context, customerID int64) (*Customer, error)
+}
+
+// PreferencesRepository holds the preferences replicated from
+// customer.preferences.changed events.
+type PreferencesRepository interface {
+	// UpsertPreferences ignores a copy older than the stored one, so a
+	// redelivered event cannot undo a later change.
+	UpsertPreferences(ctx context.Context, p *Preferences) error
+
+	// FindPreferences returns the zero Preferences for a customer without a
+	// stored copy.
+	FindPreferences(ctx context.Context, customerID int64) (*Preferences, error)
+}
+
+// ContactsRepository holds the contacts replicated from
+// customer.contacts.changed events, each of which carries the full list.
+type ContactsRepository interface {
+	// UpsertContacts ignores a copy older than the stored one.
+	UpsertContacts(ctx context.Context, c *Contacts) error
+
+	// FindContacts returns empty Contacts for a customer without a stored
+	// copy.
+	FindContacts(ctx context.Context, customerID int64) (*Contacts, error)
 }
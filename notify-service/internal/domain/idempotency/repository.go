@@ -0,0 +1,12 @@
+package idempotency
+
+import "context"
+
+// Repository remembers the IDs of events that were applied, so that a
+// redelivered message is recognised and skipped.
+type Repository interface {
+	IsProcessed(ctx context.Context, eventID string) (bool, error)
+
+	// MarkProcessed records eventID. Marking an ID twice is not an error.
+	MarkProcessed(ctx context.Context, eventID, routingKey string) error
+}
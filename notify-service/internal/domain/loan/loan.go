@@ -0,0 +1,28 @@
+package loan
+
+import "time"
+
+type Status string
+
+const (
+	StatusActive     Status = "ACTIVE"
+	StatusDelinquent Status = "DELINQUENT"
+	StatusPaidOff    Status = "PAID_OFF"
+)
+
+// Loan is the local copy of a billing-engine loan, kept for addressing loan
+// notifications. AmountPaid adds up the payments received so far.
+// StatusChangedAt is the event time of the last status change and orders
+// status events that arrive out of order.
+type Loan struct {
+	LoanID          int64
+	CustomerID      int64
+	PrincipalAmount float64
+	TermWeeks       int
+	AmountPaid      float64
+	Status          Status
+	StatusChangedAt time.Time
+	LastPaymentAt   *time.Time
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
@@ -0,0 +1,25 @@
+package loan
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrNotFound = errors.New("loan not found")
+
+type Repository interface {
+	// Create stores a new loan. A loan that already exists is left as it is.
+	Create(ctx context.Context, l *Loan) error
+
+	// RecordPayment adds amount to what was paid on the loan and returns the
+	// updated loan. It returns ErrNotFound until the loan has been replicated.
+	RecordPayment(ctx context.Context, loanID int64, amount float64, at time.Time) (*Loan, error)
+
+	// UpdateStatus sets the status unless a newer change was already applied,
+	// and returns the loan as stored. It returns ErrNotFound until the loan has
+	// been replicated.
+	UpdateStatus(ctx context.Context, loanID int64, status Status, at time.Time) (*Loan, error)
+
+	FindByID(ctx context.Context, loanID int64) (*Loan, error)
+}
@@ -0,0 +1,82 @@
+package notification
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// DispatcherConfig tunes the deferred notification loop.
+type DispatcherConfig struct {
+	Interval  time.Duration
+	BatchSize int
+	Lease     time.Duration
+}
+
+// Dispatcher periodically sends deferred notifications once their quiet
+// hours or daily cap have passed.
+type Dispatcher struct {
+	service    Service
+	cfg        DispatcherConfig
+	logger     *slog.Logger
+	wg         sync.WaitGroup
+	cancelFunc context.CancelFunc
+}
+
+func NewDispatcher(service Service, cfg DispatcherConfig, logger *slog.Logger) *Dispatcher {
+	if service == nil {
+		panic("notification service cannot be nil for Dispatcher")
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 50
+	}
+	if cfg.Lease <= 0 {
+		cfg.Lease = 5 * time.Minute
+	}
+	return &Dispatcher{
+		service: service,
+		cfg:     cfg,
+		logger:  logger.With("component", "NotificationDispatcher"),
+	}
+}
+
+func (d *Dispatcher) Start(ctx context.Context) {
+	loopCtx, cancel := context.WithCancel(ctx)
+	d.cancelFunc = cancel
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.logger.Info("Notification dispatcher started.", "interval", d.cfg.Interval)
+		ticker := time.NewTicker(d.cfg.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-loopCtx.Done():
+				d.logger.Info("Notification dispatcher context cancelled. Exiting loop.")
+				return
+			case <-ticker.C:
+				sent, err := d.service.DispatchDeferred(loopCtx, d.cfg.Lease, d.cfg.BatchSize)
+				if err != nil {
+					d.logger.Error("Deferred notification run failed", slog.Any("error", err))
+				} else if sent > 0 {
+					d.logger.Info("Sent deferred notifications", slog.Int("sent", sent))
+				}
+			}
+		}
+	}()
+}
+
+func (d *Dispatcher) Stop() {
+	if d.cancelFunc == nil {
+		return
+	}
+	d.logger.Info("Stopping notification dispatcher...")
+	d.cancelFunc()
+	d.wg.Wait()
+	d.logger.Info("Notification dispatcher stopped.")
+}
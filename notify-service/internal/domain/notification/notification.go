@@ -0,0 +1,83 @@
+package notification
+
+import (
+	"notify-service/internal/domain/customer"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusSent   Status = "SENT"
+	StatusFailed Status = "FAILED"
+
+	// StatusDeferred marks a message held back by quiet hours or the daily
+	// cap. It is sent once DeliverAfter has passed.
+	StatusDeferred Status = "DEFERRED"
+
+	// StatusSuppressed marks a message the customer opted out of. It is
+	// never sent.
+	StatusSuppressed Status = "SUPPRESSED"
+)
+
+const (
+	EventPaymentReminder  = "payment_reminder"
+	EventPaymentReceipt   = "payment_receipt"
+	EventLoanConfirmation = "loan_confirmation"
+	EventLoanPaidOff      = "loan_paid_off"
+
+	// EventContactVerification carries a code that proves the customer holds
+	// the contact. It goes to that contact, whatever the customer prefers.
+	EventContactVerification = "contact_verification"
+
+	// EventDelinquencyNotice tells a customer they were flagged delinquent
+	// or that their loan moved to a later DPD bucket. billing-engine raises
+	// it once per change, unlike the reminders that follow the ladder.
+	EventDelinquencyNotice = "delinquency_notice"
+)
+
+// Category is the opt-out category of event. Every event above but the
+// verification code is about the customer's own loans; anything else counts
+// as marketing.
+func Category(event string) string {
+	switch event {
+	case EventContactVerification:
+		return customer.CategoryVerification
+	case EventPaymentReminder, EventPaymentReceipt, EventLoanConfirmation, EventLoanPaidOff, EventDelinquencyNotice:
+		return customer.CategoryTransactional
+	default:
+		return customer.CategoryMarketing
+	}
+}
+
+// Message is what a Sender delivers. Recipient is channel specific, for
+// example a phone number for SMS.
+type Message struct {
+	Channel   string
+	Recipient string
+	Subject   string
+	Body      string
+}
+
+// Notification is one delivery attempt as recorded in the notification log.
+// SentAt is set only when Status is StatusSent, DeliverAfter only when it is
+// StatusDeferred. Subject and Body are kept so deferred messages can be sent
+// later.
+type Notification struct {
+	ID           int64
+	CustomerID   int64
+	Event        string
+	Channel      string
+	Recipient    string
+	Subject      string
+	Body         string
+	Status       Status
+	Error        string
+	SentAt       *time.Time
+	DeliverAfter *time.Time
+	CreatedAt    time.Time
+}
+
+func (n *Notification) Message() Message {
+	return Message{Channel: n.Channel, Recipient: n.Recipient, Subject: n.Subject, Body: n.Body}
+}
@@ -0,0 +1,26 @@
+package notification
+
+import (
+	"context"
+	"time"
+)
+
+type Repository interface {
+	Create(ctx context.Context, n *Notification) error
+
+	// ListByCustomer returns the most recent notifications of a customer
+	// first, at most limit of them.
+	ListByCustomer(ctx context.Context, customerID int64, limit int) ([]Notification, error)
+
+	// CountSentSince counts the customer's notifications sent at or after
+	// since, over all channels.
+	CountSentSince(ctx context.Context, customerID int64, since time.Time) (int, error)
+
+	// ClaimDeferred returns deferred notifications that are due at now and
+	// pushes their DeliverAfter to leaseUntil, so other instances skip them
+	// while they are sent.
+	ClaimDeferred(ctx context.Context, now, leaseUntil time.Time, limit int) ([]Notification, error)
+
+	// UpdateDelivery stores the Status, Error, SentAt and DeliverAfter of n.
+	UpdateDelivery(ctx context.Context, n *Notification) error
+}
@@ -0,0 +1,68 @@
+package notification
+
+import (
+	"fmt"
+	"time"
+)
+
+// QuietHours is a daily window during which a channel sends nothing. Start
+// and End are offsets from local midnight; a Start later than End wraps past
+// midnight, as in 21:00 to 08:00.
+type QuietHours struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// ParseClock parses a wall clock time such as "21:00" into an offset from
+// midnight.
+func ParseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid clock time %q, use HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Rules hold back messages instead of sending them at once. Location is the
+// customers' local time zone; a DailyCap of zero means no cap.
+type Rules struct {
+	Location   *time.Location
+	QuietHours map[string]QuietHours
+	DailyCap   int
+}
+
+func (r Rules) location() *time.Location {
+	if r.Location == nil {
+		return time.Local
+	}
+	return r.Location
+}
+
+// startOfDay returns local midnight of the day t falls on.
+func (r Rules) startOfDay(t time.Time) time.Time {
+	local := t.In(r.location())
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, r.location())
+}
+
+// quietUntil returns when the quiet hours of channel that cover t end, or
+// false if the channel may send at t.
+func (r Rules) quietUntil(channel string, t time.Time) (time.Time, bool) {
+	q, ok := r.QuietHours[channel]
+	if !ok || q.Start == q.End {
+		return time.Time{}, false
+	}
+
+	day := r.startOfDay(t)
+	offset := t.Sub(day)
+	switch {
+	case q.Start < q.End:
+		if offset >= q.Start && offset < q.End {
+			return day.Add(q.End), true
+		}
+	case offset >= q.Start:
+		return day.AddDate(0, 0, 1).Add(q.End), true
+	case offset < q.End:
+		return day.Add(q.End), true
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,53 @@
+package notification
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseClock(t *testing.T) {
+	d, err := ParseClock("21:30")
+	require.NoError(t, err)
+	assert.Equal(t, 21*time.Hour+30*time.Minute, d)
+
+	_, err = ParseClock("9pm")
+	assert.Error(t, err)
+}
+
+func TestQuietUntil(t *testing.T) {
+	jakarta := time.FixedZone("WIB", 7*60*60)
+	rules := Rules{
+		Location: jakarta,
+		QuietHours: map[string]QuietHours{
+			"sms":   {Start: 21 * time.Hour, End: 8 * time.Hour},
+			"email": {Start: 12 * time.Hour, End: 13 * time.Hour},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		channel   string
+		at        time.Time
+		wantQuiet bool
+		wantUntil time.Time
+	}{
+		{"sms late evening", "sms", time.Date(2025, 4, 1, 22, 15, 0, 0, jakarta), true, time.Date(2025, 4, 2, 8, 0, 0, 0, jakarta)},
+		{"sms early morning", "sms", time.Date(2025, 4, 2, 6, 0, 0, 0, jakarta), true, time.Date(2025, 4, 2, 8, 0, 0, 0, jakarta)},
+		{"sms daytime", "sms", time.Date(2025, 4, 2, 8, 0, 0, 0, jakarta), false, time.Time{}},
+		{"sms converts from UTC", "sms", time.Date(2025, 4, 1, 15, 0, 0, 0, time.UTC), true, time.Date(2025, 4, 2, 8, 0, 0, 0, jakarta)},
+		{"email lunch window", "email", time.Date(2025, 4, 2, 12, 30, 0, 0, jakarta), true, time.Date(2025, 4, 2, 13, 0, 0, 0, jakarta)},
+		{"channel without rule", "log", time.Date(2025, 4, 1, 23, 0, 0, 0, jakarta), false, time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			until, quiet := rules.quietUntil(tt.channel, tt.at)
+			assert.Equal(t, tt.wantQuiet, quiet)
+			if tt.wantQuiet {
+				assert.True(t, tt.wantUntil.Equal(until), "expected %v, got %v", tt.wantUntil, until)
+			}
+		})
+	}
+}
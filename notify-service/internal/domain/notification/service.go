@@ -0,0 +1,283 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"notify-service/internal/domain/customer"
+	"notify-service/internal/infrastructure/monitoring"
+	"os"
+	"time"
+)
+
+const (
+	DefaultListLimit = 50
+	MaxListLimit     = 200
+)
+
+var ErrUnknownChannel = errors.New("no sender configured for channel")
+
+// Sender delivers messages over one channel.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+type Service interface {
+	// Notify sends msg and records the outcome in the notification log. The
+	// returned error is the delivery error, so callers can retry; the failed
+	// attempt is logged either way. A message held back by quiet hours or the
+	// daily cap is recorded as deferred and reported as success, and so is
+	// one the customer opted out of, recorded as suppressed. A preferred
+	// channel replaces msg.Channel when a sender exists for it, and the
+	// customer's verified contact on that channel replaces msg.Recipient.
+	// Verification codes keep their channel and recipient and are never
+	// held back.
+	Notify(ctx context.Context, customerID int64, event string, msg Message) (*Notification, error)
+
+	// DispatchDeferred sends up to limit deferred notifications that are due
+	// and returns how many went out. Claimed rows are hidden from other
+	// instances for lease. A customer who opted out meanwhile has the
+	// notification suppressed.
+	DispatchDeferred(ctx context.Context, lease time.Duration, limit int) (int, error)
+
+	ListByCustomer(ctx context.Context, customerID int64, limit int) ([]Notification, error)
+}
+
+var _ Service = (*service)(nil)
+
+type service struct {
+	repo        Repository
+	senders     map[string]Sender
+	rules       Rules
+	preferences customer.PreferencesRepository
+	contacts    customer.ContactsRepository
+	logger      *slog.Logger
+	now         func() time.Time
+}
+
+// NewService builds the service. With nil preferences every message goes out
+// on the channel it was addressed to, and with nil contacts to the recipient
+// it was addressed to.
+func NewService(repo Repository, senders map[string]Sender, rules Rules, preferences customer.PreferencesRepository, contacts customer.ContactsRepository, logger *slog.Logger) Service {
+	if repo == nil {
+		panic("notification repository cannot be nil")
+	}
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+		logger.Warn("Warning: No logger provided to notification.NewService, using default stderr handler")
+	}
+	return &service{
+		repo:        repo,
+		senders:     senders,
+		rules:       rules,
+		preferences: preferences,
+		contacts:    contacts,
+		logger:      logger.With("component", "NotificationService"),
+		now:         time.Now,
+	}
+}
+
+func (s *service) Notify(ctx context.Context, customerID int64, event string, msg Message) (*Notification, error) {
+	prefs, err := s.findPreferences(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+	verification := event == EventContactVerification
+	if !verification {
+		if _, ok := s.senders[prefs.PreferredChannel]; ok {
+			msg.Channel = prefs.PreferredChannel
+		}
+		if msg.Recipient, err = s.recipient(ctx, customerID, msg); err != nil {
+			return nil, err
+		}
+	}
+	logCtx := s.logger.With(slog.Int64("customerID", customerID), slog.String("event", event), slog.String("channel", msg.Channel))
+
+	n := &Notification{
+		CustomerID: customerID,
+		Event:      event,
+		Channel:    msg.Channel,
+		Recipient:  msg.Recipient,
+		Subject:    msg.Subject,
+		Body:       msg.Body,
+	}
+
+	if prefs.OptedOut(Category(event)) {
+		n.Status = StatusSuppressed
+		if err := s.repo.Create(ctx, n); err != nil {
+			return nil, err
+		}
+		monitoring.RecordNotification(n.Event, n.Channel, string(n.Status))
+		logCtx.InfoContext(ctx, "Notification suppressed, customer opted out", slog.String("category", Category(event)))
+		return n, nil
+	}
+
+	// A verification code is only good for minutes, so it is never held
+	// back.
+	if !verification {
+		deliverAfter, reason, err := s.holdUntil(ctx, customerID, msg.Channel, s.now())
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			n.Status = StatusDeferred
+			n.DeliverAfter = &deliverAfter
+			if err := s.repo.Create(ctx, n); err != nil {
+				return nil, err
+			}
+			monitoring.RecordNotification(n.Event, n.Channel, string(n.Status))
+			logCtx.InfoContext(ctx, "Notification deferred", slog.String("reason", reason), slog.Time("deliverAfter", deliverAfter))
+			return n, nil
+		}
+	}
+
+	sendErr := s.deliver(ctx, logCtx, n)
+	if verification {
+		// The log keeps no copy of the code.
+		n.Body = ""
+	}
+	if err := s.repo.Create(ctx, n); err != nil {
+		logCtx.ErrorContext(ctx, "Failed to record notification", slog.Any("error", err))
+		if sendErr == nil {
+			// The message went out; failing here would make the caller
+			// send it a second time.
+			return n, nil
+		}
+		return n, errors.Join(sendErr, err)
+	}
+	return n, sendErr
+}
+
+// DispatchDeferred checks the rules again before sending, since a due message
+// can still hit the cap when several were deferred to the same morning. A
+// deferred message that fails to send is recorded as failed and not retried.
+func (s *service) DispatchDeferred(ctx context.Context, lease time.Duration, limit int) (int, error) {
+	now := s.now()
+	due, err := s.repo.ClaimDeferred(ctx, now, now.Add(lease), limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim deferred notifications: %w", err)
+	}
+
+	sent := 0
+	for i := range due {
+		n := &due[i]
+		logCtx := s.logger.With(slog.Int64("notificationID", n.ID), slog.Int64("customerID", n.CustomerID), slog.String("channel", n.Channel))
+
+		prefs, err := s.findPreferences(ctx, n.CustomerID)
+		if err != nil {
+			logCtx.ErrorContext(ctx, "Failed to check customer preferences", slog.Any("error", err))
+			continue
+		}
+		if prefs.OptedOut(Category(n.Event)) {
+			n.Status = StatusSuppressed
+			n.DeliverAfter = nil
+			monitoring.RecordNotification(n.Event, n.Channel, string(n.Status))
+			logCtx.InfoContext(ctx, "Deferred notification suppressed, customer opted out")
+		} else {
+			deliverAfter, reason, err := s.holdUntil(ctx, n.CustomerID, n.Channel, s.now())
+			if err != nil {
+				logCtx.ErrorContext(ctx, "Failed to check notification rules", slog.Any("error", err))
+				continue
+			}
+			if reason != "" {
+				n.DeliverAfter = &deliverAfter
+			} else if s.deliver(ctx, logCtx, n) == nil {
+				sent++
+			}
+		}
+		if err := s.repo.UpdateDelivery(ctx, n); err != nil {
+			logCtx.ErrorContext(ctx, "Failed to record deferred notification", slog.Any("error", err))
+		}
+	}
+	return sent, nil
+}
+
+func (s *service) findPreferences(ctx context.Context, customerID int64) (*customer.Preferences, error) {
+	if s.preferences == nil {
+		return &customer.Preferences{CustomerID: customerID}, nil
+	}
+	prefs, err := s.preferences.FindPreferences(ctx, customerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load preferences of customer %d: %w", customerID, err)
+	}
+	return prefs, nil
+}
+
+// recipient is the customer's verified contact on msg's channel, or
+// msg.Recipient when the customer has none there.
+func (s *service) recipient(ctx context.Context, customerID int64, msg Message) (string, error) {
+	if s.contacts == nil {
+		return msg.Recipient, nil
+	}
+	contacts, err := s.contacts.FindContacts(ctx, customerID)
+	if err != nil {
+		return "", fmt.Errorf("failed to load contacts of customer %d: %w", customerID, err)
+	}
+	if recipient, ok := contacts.Recipient(msg.Channel); ok {
+		return recipient, nil
+	}
+	return msg.Recipient, nil
+}
+
+// holdUntil applies the rules to a message for customerID on channel at t. A
+// non-empty reason means the message must wait until the returned time.
+func (s *service) holdUntil(ctx context.Context, customerID int64, channel string, t time.Time) (time.Time, string, error) {
+	at, reason := t, ""
+	if s.rules.DailyCap > 0 {
+		day := s.rules.startOfDay(t)
+		count, err := s.repo.CountSentSince(ctx, customerID, day)
+		if err != nil {
+			return time.Time{}, "", fmt.Errorf("failed to count notifications of customer %d: %w", customerID, err)
+		}
+		if count >= s.rules.DailyCap {
+			at, reason = day.AddDate(0, 0, 1), "daily cap"
+		}
+	}
+	if until, quiet := s.rules.quietUntil(channel, at); quiet {
+		if reason == "" {
+			reason = "quiet hours"
+		}
+		at = until
+	}
+	return at, reason, nil
+}
+
+// deliver sends n and sets its outcome fields.
+func (s *service) deliver(ctx context.Context, logCtx *slog.Logger, n *Notification) error {
+	n.DeliverAfter = nil
+	err := s.send(ctx, n.Message())
+	if err != nil {
+		n.Status = StatusFailed
+		n.Error = err.Error()
+		monitoring.RecordNotification(n.Event, n.Channel, string(n.Status))
+		logCtx.WarnContext(ctx, "Notification delivery failed", slog.Any("error", err))
+		return err
+	}
+	sentAt := s.now()
+	n.Status = StatusSent
+	n.SentAt = &sentAt
+	monitoring.RecordNotification(n.Event, n.Channel, string(n.Status))
+	logCtx.InfoContext(ctx, "Notification sent")
+	return nil
+}
+
+func (s *service) send(ctx context.Context, msg Message) error {
+	sender, ok := s.senders[msg.Channel]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownChannel, msg.Channel)
+	}
+	return sender.Send(ctx, msg)
+}
+
+func (s *service) ListByCustomer(ctx context.Context, customerID int64, limit int) ([]Notification, error) {
+	if limit <= 0 {
+		limit = DefaultListLimit
+	}
+	limit = min(limit, MaxListLimit)
+	notifications, err := s.repo.ListByCustomer(ctx, customerID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notifications of customer %d: %w", customerID, err)
+	}
+	return notifications, nil
+}
@@ -0,0 +1,337 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"notify-service/internal/domain/customer"
+	"notify-service/internal/infrastructure/monitoring"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeRepository struct {
+	created   []Notification
+	createErr error
+	listLimit int
+	sentToday int
+	due       []Notification
+	updated   []Notification
+}
+
+func (f *fakeRepository) Create(ctx context.Context, n *Notification) error {
+	if f.createErr != nil {
+		return f.createErr
+	}
+	n.ID = int64(len(f.created) + 1)
+	f.created = append(f.created, *n)
+	return nil
+}
+
+func (f *fakeRepository) ListByCustomer(ctx context.Context, customerID int64, limit int) ([]Notification, error) {
+	f.listLimit = limit
+	return f.created, nil
+}
+
+func (f *fakeRepository) CountSentSince(ctx context.Context, customerID int64, since time.Time) (int, error) {
+	return f.sentToday, nil
+}
+
+func (f *fakeRepository) ClaimDeferred(ctx context.Context, now, leaseUntil time.Time, limit int) ([]Notification, error) {
+	return f.due, nil
+}
+
+func (f *fakeRepository) UpdateDelivery(ctx context.Context, n *Notification) error {
+	f.updated = append(f.updated, *n)
+	return nil
+}
+
+type senderFunc func(ctx context.Context, msg Message) error
+
+func (f senderFunc) Send(ctx context.Context, msg Message) error { return f(ctx, msg) }
+
+var testNow = time.Date(2025, 4, 1, 9, 0, 0, 0, time.UTC)
+
+func newTestService(repo Repository, sender senderFunc) *service {
+	return newTestServiceWithRules(repo, sender, Rules{Location: time.UTC})
+}
+
+func newTestServiceWithRules(repo Repository, sender senderFunc, rules Rules) *service {
+	s := NewService(repo, map[string]Sender{"sms": sender}, rules, nil, nil, slog.New(slog.NewTextHandler(io.Discard, nil))).(*service)
+	s.now = func() time.Time { return testNow }
+	return s
+}
+
+func TestNotifyRecordsSentNotification(t *testing.T) {
+	repo := &fakeRepository{}
+	s := newTestService(repo, func(context.Context, Message) error { return nil })
+	sent := monitoring.Business.NotificationsTotal.WithLabelValues(EventDelinquencyNotice, "sms", string(StatusSent))
+	sentBefore := testutil.ToFloat64(sent)
+
+	n, err := s.Notify(context.Background(), 7, EventDelinquencyNotice, Message{Channel: "sms", Recipient: "+6281234"})
+
+	require.NoError(t, err)
+	require.Len(t, repo.created, 1)
+	assert.Equal(t, StatusSent, n.Status)
+	assert.Equal(t, sentBefore+1, testutil.ToFloat64(sent))
+	require.NotNil(t, n.SentAt)
+	assert.Equal(t, "+6281234", repo.created[0].Recipient)
+}
+
+func TestNotifyRecordsFailedNotification(t *testing.T) {
+	repo := &fakeRepository{}
+	s := newTestService(repo, func(context.Context, Message) error { return errors.New("gateway timeout") })
+
+	n, err := s.Notify(context.Background(), 7, EventDelinquencyNotice, Message{Channel: "sms"})
+
+	assert.EqualError(t, err, "gateway timeout")
+	assert.Equal(t, StatusFailed, n.Status)
+	assert.Equal(t, "gateway timeout", repo.created[0].Error)
+	assert.Nil(t, repo.created[0].SentAt)
+}
+
+func TestNotifyUnknownChannel(t *testing.T) {
+	repo := &fakeRepository{}
+	s := newTestService(repo, nil)
+
+	_, err := s.Notify(context.Background(), 7, EventDelinquencyNotice, Message{Channel: "fax"})
+
+	assert.ErrorIs(t, err, ErrUnknownChannel)
+	assert.Equal(t, StatusFailed, repo.created[0].Status)
+}
+
+func TestNotifyKeepsSuccessWhenLogWriteFails(t *testing.T) {
+	repo := &fakeRepository{createErr: errors.New("db down")}
+	s := newTestService(repo, func(context.Context, Message) error { return nil })
+
+	_, err := s.Notify(context.Background(), 7, EventDelinquencyNotice, Message{Channel: "sms"})
+
+	assert.NoError(t, err)
+}
+
+func TestListByCustomerClampsLimit(t *testing.T) {
+	repo := &fakeRepository{}
+	s := newTestService(repo, nil)
+
+	_, err := s.ListByCustomer(context.Background(), 7, 0)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultListLimit, repo.listLimit)
+
+	_, err = s.ListByCustomer(context.Background(), 7, 10_000)
+	require.NoError(t, err)
+	assert.Equal(t, MaxListLimit, repo.listLimit)
+}
+
+func TestNotifyDefersDuringQuietHours(t *testing.T) {
+	repo := &fakeRepository{}
+	rules := Rules{Location: time.UTC, QuietHours: map[string]QuietHours{"sms": {Start: 21 * time.Hour, End: 10 * time.Hour}}}
+	s := newTestServiceWithRules(repo, func(context.Context, Message) error {
+		t.Fatal("message sent during quiet hours")
+		return nil
+	}, rules)
+
+	n, err := s.Notify(context.Background(), 7, EventDelinquencyNotice, Message{Channel: "sms", Body: "overdue"})
+
+	require.NoError(t, err)
+	assert.Equal(t, StatusDeferred, n.Status)
+	assert.Equal(t, time.Date(2025, 4, 1, 10, 0, 0, 0, time.UTC), *n.DeliverAfter)
+	assert.Equal(t, "overdue", repo.created[0].Body)
+}
+
+func TestNotifyDefersOverDailyCap(t *testing.T) {
+	repo := &fakeRepository{sentToday: 3}
+	rules := Rules{Location: time.UTC, DailyCap: 3, QuietHours: map[string]QuietHours{"sms": {Start: 21 * time.Hour, End: 8 * time.Hour}}}
+	s := newTestServiceWithRules(repo, nil, rules)
+
+	n, err := s.Notify(context.Background(), 7, EventDelinquencyNotice, Message{Channel: "sms"})
+
+	require.NoError(t, err)
+	assert.Equal(t, StatusDeferred, n.Status)
+	// Next midnight falls in quiet hours, so the message waits until 08:00.
+	assert.Equal(t, time.Date(2025, 4, 2, 8, 0, 0, 0, time.UTC), *n.DeliverAfter)
+}
+
+func TestDispatchDeferred(t *testing.T) {
+	var sent []Message
+	repo := &fakeRepository{due: []Notification{
+		{ID: 1, CustomerID: 7, Channel: "sms", Body: "overdue", Status: StatusDeferred},
+		{ID: 2, CustomerID: 8, Channel: "fax", Status: StatusDeferred},
+	}}
+	s := newTestService(repo, func(ctx context.Context, msg Message) error {
+		sent = append(sent, msg)
+		return nil
+	})
+
+	count, err := s.DispatchDeferred(context.Background(), time.Minute, 10)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	require.Len(t, sent, 1)
+	assert.Equal(t, "overdue", sent[0].Body)
+	require.Len(t, repo.updated, 2)
+	assert.Equal(t, StatusSent, repo.updated[0].Status)
+	assert.Nil(t, repo.updated[0].DeliverAfter)
+	assert.Equal(t, StatusFailed, repo.updated[1].Status)
+}
+
+func TestDispatchDeferredKeepsCappedMessages(t *testing.T) {
+	repo := &fakeRepository{sentToday: 1, due: []Notification{{ID: 1, CustomerID: 7, Channel: "sms", Status: StatusDeferred}}}
+	s := newTestServiceWithRules(repo, nil, Rules{Location: time.UTC, DailyCap: 1})
+
+	count, err := s.DispatchDeferred(context.Background(), time.Minute, 10)
+
+	require.NoError(t, err)
+	assert.Zero(t, count)
+	require.Len(t, repo.updated, 1)
+	assert.Equal(t, StatusDeferred, repo.updated[0].Status)
+	assert.Equal(t, time.Date(2025, 4, 2, 0, 0, 0, 0, time.UTC), *repo.updated[0].DeliverAfter)
+}
+
+type fakePreferences map[int64]customer.Preferences
+
+func (f fakePreferences) UpsertPreferences(ctx context.Context, p *customer.Preferences) error {
+	f[p.CustomerID] = *p
+	return nil
+}
+
+func (f fakePreferences) FindPreferences(ctx context.Context, customerID int64) (*customer.Preferences, error) {
+	p := f[customerID]
+	p.CustomerID = customerID
+	return &p, nil
+}
+
+func TestNotifySuppressesOptedOutCategory(t *testing.T) {
+	repo := &fakeRepository{}
+	s := newTestService(repo, func(context.Context, Message) error {
+		t.Fatal("an opted out message must not be sent")
+		return nil
+	})
+	s.preferences = fakePreferences{7: {TransactionalOptOut: true}}
+
+	n, err := s.Notify(context.Background(), 7, EventPaymentReceipt, Message{Channel: "sms", Recipient: "+6281234"})
+
+	require.NoError(t, err)
+	require.Len(t, repo.created, 1)
+	assert.Equal(t, StatusSuppressed, n.Status)
+	assert.Nil(t, n.SentAt)
+}
+
+func TestNotifyIgnoresOtherCategoryOptOut(t *testing.T) {
+	repo := &fakeRepository{}
+	s := newTestService(repo, func(context.Context, Message) error { return nil })
+	s.preferences = fakePreferences{7: {MarketingOptOut: true}}
+
+	n, err := s.Notify(context.Background(), 7, EventDelinquencyNotice, Message{Channel: "sms"})
+
+	require.NoError(t, err)
+	assert.Equal(t, StatusSent, n.Status)
+}
+
+func TestNotifyUsesPreferredChannel(t *testing.T) {
+	var emailed []Message
+	repo := &fakeRepository{}
+	s := newTestService(repo, func(context.Context, Message) error { return nil })
+	s.senders["email"] = senderFunc(func(ctx context.Context, msg Message) error {
+		emailed = append(emailed, msg)
+		return nil
+	})
+	s.preferences = fakePreferences{7: {PreferredChannel: "email"}, 8: {PreferredChannel: "push"}}
+
+	n, err := s.Notify(context.Background(), 7, EventDelinquencyNotice, Message{Channel: "sms", Recipient: "a@example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, "email", n.Channel)
+	require.Len(t, emailed, 1)
+	assert.Equal(t, "email", emailed[0].Channel)
+
+	n, err = s.Notify(context.Background(), 8, EventDelinquencyNotice, Message{Channel: "sms"})
+	require.NoError(t, err)
+	assert.Equal(t, "sms", n.Channel, "a channel without a sender keeps the original one")
+}
+
+func TestDispatchDeferredSuppressesOptedOut(t *testing.T) {
+	deliverAfter := testNow.Add(-time.Minute)
+	repo := &fakeRepository{due: []Notification{{ID: 1, CustomerID: 7, Event: EventLoanPaidOff, Channel: "sms", Status: StatusDeferred, DeliverAfter: &deliverAfter}}}
+	s := newTestService(repo, nil)
+	s.preferences = fakePreferences{7: {TransactionalOptOut: true}}
+
+	count, err := s.DispatchDeferred(context.Background(), time.Minute, 10)
+
+	require.NoError(t, err)
+	assert.Zero(t, count)
+	require.Len(t, repo.updated, 1)
+	assert.Equal(t, StatusSuppressed, repo.updated[0].Status)
+	assert.Nil(t, repo.updated[0].DeliverAfter)
+}
+
+func TestCategory(t *testing.T) {
+	assert.Equal(t, customer.CategoryTransactional, Category(EventPaymentReceipt))
+	assert.Equal(t, customer.CategoryMarketing, Category("spring_promotion"))
+	assert.Equal(t, customer.CategoryVerification, Category(EventContactVerification))
+}
+
+type fakeContacts map[int64]customer.Contacts
+
+func (f fakeContacts) UpsertContacts(ctx context.Context, c *customer.Contacts) error {
+	f[c.CustomerID] = *c
+	return nil
+}
+
+func (f fakeContacts) FindContacts(ctx context.Context, customerID int64) (*customer.Contacts, error) {
+	c := f[customerID]
+	c.CustomerID = customerID
+	return &c, nil
+}
+
+func TestNotifySendsToVerifiedContact(t *testing.T) {
+	var sent []Message
+	repo := &fakeRepository{}
+	s := newTestService(repo, func(ctx context.Context, msg Message) error {
+		sent = append(sent, msg)
+		return nil
+	})
+	s.contacts = fakeContacts{7: {Contacts: []customer.Contact{
+		{ContactID: 1, Type: customer.ContactPhone, Value: "+6281111111111", Verified: true},
+		{ContactID: 2, Type: customer.ContactPhone, Value: "+6282222222222", Verified: true, Primary: true},
+	}}, 8: {Contacts: []customer.Contact{
+		{ContactID: 3, Type: customer.ContactPhone, Value: "+6283333333333"},
+	}}}
+
+	_, err := s.Notify(context.Background(), 7, EventPaymentReminder, Message{Channel: "sms", Recipient: "+6281234"})
+	require.NoError(t, err)
+	_, err = s.Notify(context.Background(), 8, EventPaymentReminder, Message{Channel: "sms", Recipient: "+6281234"})
+	require.NoError(t, err)
+
+	require.Len(t, sent, 2)
+	assert.Equal(t, "+6282222222222", sent[0].Recipient, "the primary contact wins")
+	assert.Equal(t, "+6282222222222", repo.created[0].Recipient)
+	assert.Equal(t, "+6281234", sent[1].Recipient, "an unverified contact is not sent to")
+}
+
+func TestNotifyContactVerification(t *testing.T) {
+	var sent []Message
+	repo := &fakeRepository{sentToday: 5}
+	s := newTestServiceWithRules(repo, func(ctx context.Context, msg Message) error {
+		sent = append(sent, msg)
+		return nil
+	}, Rules{Location: time.UTC, DailyCap: 5})
+	s.senders["email"] = senderFunc(func(context.Context, Message) error { return nil })
+	s.preferences = fakePreferences{7: {PreferredChannel: "email", TransactionalOptOut: true, MarketingOptOut: true}}
+	s.contacts = fakeContacts{7: {Contacts: []customer.Contact{
+		{ContactID: 1, Type: customer.ContactPhone, Value: "+6281111111111", Verified: true, Primary: true},
+	}}}
+
+	n, err := s.Notify(context.Background(), 7, EventContactVerification, Message{Channel: "sms", Recipient: "+6289999999999", Body: "Your code is 482913"})
+
+	require.NoError(t, err)
+	assert.Equal(t, StatusSent, n.Status, "neither opt-outs nor the daily cap hold a code back")
+	require.Len(t, sent, 1)
+	assert.Equal(t, Message{Channel: "sms", Recipient: "+6289999999999", Body: "Your code is 482913"}, sent[0],
+		"the code goes to the contact being verified")
+	require.Len(t, repo.created, 1)
+	assert.Empty(t, repo.created[0].Body, "the log keeps no copy of the code")
+}
@@ -0,0 +1,22 @@
+package retry
+
+import (
+	"context"
+	"time"
+)
+
+type Repository interface {
+	// Enqueue stores a failed delivery, due at nextAttemptAt.
+	Enqueue(ctx context.Context, routingKey string, body []byte, lastError string, nextAttemptAt time.Time) error
+
+	// ClaimDue returns up to limit retries due at now and pushes their
+	// next_attempt_at to leaseUntil, so that a crashed worker's claims become
+	// due again and concurrent workers never pick the same row.
+	ClaimDue(ctx context.Context, now, leaseUntil time.Time, limit int) ([]Retry, error)
+
+	// Reschedule records a failed attempt. A nil nextAttemptAt parks the
+	// retry: it stays in the table for inspection but is never claimed.
+	Reschedule(ctx context.Context, id int64, attempts int, lastError string, nextAttemptAt *time.Time) error
+
+	Delete(ctx context.Context, id int64) error
+}
@@ -0,0 +1,43 @@
+package retry
+
+import (
+	"time"
+)
+
+// Retry is a delivery that failed to process and waits in the retry store
+// for another attempt. Body is the original message body, so a retry goes
+// through exactly the same decoding as a live delivery.
+type Retry struct {
+	ID            int64
+	RoutingKey    string
+	Body          []byte
+	Attempts      int
+	LastError     string
+	NextAttemptAt *time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// Policy decides when a failed retry runs again. The delay doubles with each
+// attempt, starting at BaseDelay and capped at MaxDelay.
+type Policy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// Next returns when to run a retry that has now failed attempts times. ok is
+// false once MaxAttempts is reached and the retry should be parked.
+func (p Policy) Next(now time.Time, attempts int) (next time.Time, ok bool) {
+	if p.MaxAttempts > 0 && attempts >= p.MaxAttempts {
+		return time.Time{}, false
+	}
+	delay := p.BaseDelay
+	for i := 1; i < attempts && (p.MaxDelay <= 0 || delay < p.MaxDelay); i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return now.Add(delay), true
+}
@@ -0,0 +1,46 @@
+package retry
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPolicyNext(t *testing.T) {
+	now := time.Date(2025, 4, 1, 12, 0, 0, 0, time.UTC)
+	policy := Policy{MaxAttempts: 5, BaseDelay: 30 * time.Second, MaxDelay: 2 * time.Minute}
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+		ok       bool
+	}{
+		{attempts: 1, want: 30 * time.Second, ok: true},
+		{attempts: 2, want: time.Minute, ok: true},
+		{attempts: 3, want: 2 * time.Minute, ok: true},
+		{attempts: 4, want: 2 * time.Minute, ok: true},
+		{attempts: 5, ok: false},
+	}
+
+	for _, tt := range tests {
+		next, ok := policy.Next(now, tt.attempts)
+		if ok != tt.ok {
+			t.Fatalf("attempts %d: expected ok=%v, got %v", tt.attempts, tt.ok, ok)
+		}
+		if ok && next.Sub(now) != tt.want {
+			t.Errorf("attempts %d: expected delay %v, got %v", tt.attempts, tt.want, next.Sub(now))
+		}
+	}
+}
+
+func TestPolicyNextWithoutLimit(t *testing.T) {
+	now := time.Now()
+	policy := Policy{BaseDelay: time.Second}
+
+	next, ok := policy.Next(now, 4)
+	if !ok {
+		t.Fatal("expected a policy without MaxAttempts to keep retrying")
+	}
+	if next.Sub(now) != 8*time.Second {
+		t.Errorf("expected delay 8s, got %v", next.Sub(now))
+	}
+}
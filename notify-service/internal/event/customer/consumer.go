@@ -2,130 +2,230 @@ package event
 
 import (
 	"context"
+	"errors"
+	"events"
 	"fmt"
 	"log/slog"
+	"notify-service/internal/infrastructure/monitoring"
 	"sync"
+	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
 const (
-	routingKeyCustomerCreated = "customer.created"
-	routingKeyCustomerUpdated = "customer.updated"
+	routingKeyCustomerCreated = events.RoutingKeyCustomerCreated
+	routingKeyCustomerUpdated = events.RoutingKeyCustomerUpdated
+
+	routingKeyDelinquencyChanged = events.RoutingKeyCustomerDelinquencyChanged
+	routingKeyPreferencesChanged = events.RoutingKeyCustomerPreferencesChanged
+
+	routingKeyContactsChanged              = events.RoutingKeyCustomerContactsChanged
+	routingKeyContactVerificationRequested = events.RoutingKeyCustomerContactVerificationRequested
+
+	routingKeyLoanCreated         = events.RoutingKeyLoanCreated
+	routingKeyLoanPaymentReceived = events.RoutingKeyLoanPaymentReceived
+	routingKeyLoanDelinquent      = events.RoutingKeyLoanDelinquent
+	routingKeyLoanPaidOff         = events.RoutingKeyLoanPaidOff
+	routingKeyLoanReminderDue     = events.RoutingKeyLoanReminderDue
 )
 
+const defaultDrainTimeout = 30 * time.Second
+
 type MessageHandler func(ctx context.Context, d amqp.Delivery)
 
+// ConsumerConfig bounds the work the consumer takes on. PrefetchCount is how
+// many unacknowledged deliveries the broker sends ahead, and Workers how many
+// of them are handled at once; while every worker is busy the broker holds
+// the rest of the queue. Stop waits up to DrainTimeout for the handlers in
+// flight before cancelling them.
+//
+// With more than one worker, events about the same customer or loan can be
+// applied out of order. The customer and loan upserts keep the newest state
+// by its timestamp, so a late event does not overwrite a newer one.
+type ConsumerConfig struct {
+	PrefetchCount int
+	Workers       int
+	DrainTimeout  time.Duration
+}
+
+// deliveryChannel is the part of *amqp.Channel the consumer uses after setup.
+type deliveryChannel interface {
+	Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error)
+	Cancel(consumer string, noWait bool) error
+	Close() error
+}
+
+// Consumer reads every queue in queues on one channel and hands the
+// deliveries to a shared worker pool. Each queue is consumed under its own
+// tag, consumerTag followed by the queue name.
 type Consumer struct {
-	conn         *amqp.Connection
-	channel      *amqp.Channel
-	exchangeName string
-	queueName    string
-	consumerTag  string
-	handler      MessageHandler
-	logger       *slog.Logger
-	wg           *sync.WaitGroup
-	cancelFunc   context.CancelFunc
+	conn        *amqp.Connection
+	channel     deliveryChannel
+	queues      []string
+	consumerTag string
+	handler     MessageHandler
+	cfg         ConsumerConfig
+	logger      *slog.Logger
+	wg          *sync.WaitGroup
+	cancelFunc  context.CancelFunc
+	abortFunc   context.CancelFunc
 }
 
+// NewConsumer expects the queues to exist already; main declares the
+// topology before building the consumer.
 func NewConsumer(
 	conn *amqp.Connection,
-	exchangeName, queueName, consumerTag string,
+	queues []string,
+	consumerTag string,
 	handler MessageHandler,
+	cfg ConsumerConfig,
 	logger *slog.Logger,
 ) (*Consumer, error) {
+	if len(queues) == 0 {
+		return nil, errors.New("no queues configured to consume")
+	}
+	cfg = cfg.withDefaults()
+	if cfg.PrefetchCount < cfg.Workers {
+		logger.Warn("Prefetch count is lower than the worker count, some workers will stay idle",
+			"prefetchCount", cfg.PrefetchCount, "workers", cfg.Workers)
+	}
 
 	ch, err := conn.Channel()
 	if err != nil {
 		return nil, fmt.Errorf("failed to open RabbitMQ channel: %w", err)
 	}
 
-	logger.Info("Declaring exchange", "name", exchangeName, "type", amqp.ExchangeTopic)
-	err = ch.ExchangeDeclare(exchangeName, amqp.ExchangeTopic, true, false, false, false, nil)
+	err = ch.Qos(cfg.PrefetchCount, 0, false)
 	if err != nil {
 		_ = ch.Close()
-		return nil, fmt.Errorf("failed to declare exchange '%s': %w", exchangeName, err)
+		return nil, fmt.Errorf("failed to set QoS: %w", err)
 	}
 
-	logger.Info("Declaring queue", "name", queueName)
-	q, err := ch.QueueDeclare(queueName, true, false, false, false, nil)
-	if err != nil {
-		_ = ch.Close()
-		return nil, fmt.Errorf("failed to declare queue '%s': %w", queueName, err)
-	}
+	c := newConsumer(ch, queues, consumerTag, handler, cfg, logger.With("component", "consumer"))
+	c.conn = conn
+	return c, nil
+}
 
-	routingKeys := []string{routingKeyCustomerCreated, routingKeyCustomerUpdated}
-	for _, key := range routingKeys {
-		logger.Info("Binding queue", "queue", q.Name, "exchange", exchangeName, "key", key)
-		err = ch.QueueBind(q.Name, key, exchangeName, false, nil)
-		if err != nil {
-			_ = ch.Close()
-			return nil, fmt.Errorf("failed to bind queue '%s' with key '%s': %w", q.Name, key, err)
-		}
+func newConsumer(ch deliveryChannel, queues []string, consumerTag string, handler MessageHandler, cfg ConsumerConfig, logger *slog.Logger) *Consumer {
+	return &Consumer{
+		channel:     ch,
+		queues:      queues,
+		consumerTag: consumerTag,
+		handler:     handler,
+		cfg:         cfg.withDefaults(),
+		logger:      logger,
+		wg:          new(sync.WaitGroup),
 	}
+}
 
-	prefetchCount := 1
-	err = ch.Qos(prefetchCount, 0, false)
-	if err != nil {
-		_ = ch.Close()
-		return nil, fmt.Errorf("failed to set QoS: %w", err)
+// withDefaults handles one delivery at a time and prefetches one per worker
+// when nothing is configured.
+func (cfg ConsumerConfig) withDefaults() ConsumerConfig {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	if cfg.PrefetchCount <= 0 {
+		cfg.PrefetchCount = cfg.Workers
+	}
+	if cfg.DrainTimeout <= 0 {
+		cfg.DrainTimeout = defaultDrainTimeout
 	}
+	return cfg
+}
 
-	return &Consumer{
-		conn:         conn,
-		channel:      ch,
-		exchangeName: exchangeName,
-		queueName:    q.Name,
-		consumerTag:  consumerTag,
-		handler:      handler,
-		logger:       logger.With("component", "consumer", "queue", q.Name),
-		wg:           new(sync.WaitGroup),
-	}, nil
+func (c *Consumer) tag(queue string) string {
+	return c.consumerTag + "." + queue
 }
 
 func (c *Consumer) Start(ctx context.Context) error {
-	c.logger.Info("Starting message consumption...")
-	deliveries, err := c.channel.Consume(
-		c.queueName,
-		c.consumerTag,
-		false,
-		false,
-		false,
-		false,
-		nil,
-	)
-	if err != nil {
-		_ = c.channel.Close()
-		return fmt.Errorf("failed to register a consumer: %w", err)
+	c.logger.Info("Starting message consumption...", "queues", c.queues, "workers", c.cfg.Workers, "prefetchCount", c.cfg.PrefetchCount)
+	deliveries := make([]<-chan amqp.Delivery, len(c.queues))
+	for i, queue := range c.queues {
+		ds, err := c.channel.Consume(
+			queue,
+			c.tag(queue),
+			false,
+			false,
+			false,
+			false,
+			nil,
+		)
+		if err != nil {
+			_ = c.channel.Close()
+			return fmt.Errorf("failed to register a consumer on queue '%s': %w", queue, err)
+		}
+		deliveries[i] = ds
 	}
 
 	loopCtx, cancel := context.WithCancel(ctx)
 	c.cancelFunc = cancel
+	// Handlers do not inherit the cancellation of ctx, so that a shutdown
+	// signal lets the deliveries in flight finish. Stop aborts them once the
+	// drain timeout has passed.
+	handlerCtx, abort := context.WithCancel(context.WithoutCancel(ctx))
+	c.abortFunc = abort
 
+	jobs := make(chan amqp.Delivery)
+	c.wg.Add(c.cfg.Workers)
+	for range c.cfg.Workers {
+		go func() {
+			defer c.wg.Done()
+			for d := range jobs {
+				monitoring.RecordDeliveryStarted()
+				c.handler(handlerCtx, d)
+				monitoring.RecordDeliveryFinished()
+			}
+		}()
+	}
+
+	// One dispatcher per queue feeds the workers; jobs closes once all of
+	// them have returned.
+	var dispatchers sync.WaitGroup
+	dispatchers.Add(len(c.queues))
+	for i, queue := range c.queues {
+		go func() {
+			defer dispatchers.Done()
+			c.dispatch(loopCtx, c.logger.With("queue", queue), deliveries[i], jobs)
+		}()
+	}
 	c.wg.Add(1)
 	go func() {
 		defer c.wg.Done()
-		c.logger.Info("Consumer goroutine started.")
-		for {
-			select {
-			case <-loopCtx.Done():
-				c.logger.Info("Consumer context cancelled. Exiting consumption loop.")
-				return
-			case d, ok := <-deliveries:
-				if !ok {
-					c.logger.Warn("RabbitMQ delivery channel closed unexpectedly.")
+		dispatchers.Wait()
+		close(jobs)
+	}()
 
-					return
-				}
+	return nil
+}
 
-				c.handler(loopCtx, d)
+func (c *Consumer) dispatch(ctx context.Context, logger *slog.Logger, deliveries <-chan amqp.Delivery, jobs chan<- amqp.Delivery) {
+	logger.Info("Consumer goroutine started.")
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Consumer context cancelled. Exiting consumption loop.")
+			return
+		case d, ok := <-deliveries:
+			if !ok {
+				logger.Warn("RabbitMQ delivery channel closed unexpectedly.")
+				return
 			}
-		}
-	}()
 
-	return nil
+			select {
+			case jobs <- d:
+			case <-ctx.Done():
+				_ = d.Nack(false, true)
+				logger.Info("Consumer context cancelled. Requeued a delivery no worker took.")
+				return
+			}
+		}
+	}
 }
 
+// Stop stops taking deliveries and waits for the ones being handled. Those
+// still running after the drain timeout have their context cancelled; any
+// left unacknowledged go back to the queue when the channel closes.
 func (c *Consumer) Stop() {
 	if c.cancelFunc == nil {
 		c.logger.Warn("Consumer stop called but cancelFunc is nil (maybe never started?)")
@@ -135,13 +235,20 @@ func (c *Consumer) Stop() {
 
 	c.cancelFunc()
 
-	if err := c.channel.Cancel(c.consumerTag, false); err != nil {
-		c.logger.Warn("Failed to cancel consumer tag", "tag", c.consumerTag, "error", err)
+	for _, queue := range c.queues {
+		if err := c.channel.Cancel(c.tag(queue), false); err != nil {
+			c.logger.Warn("Failed to cancel consumer tag", "tag", c.tag(queue), "error", err)
+		}
 	}
 
-	c.logger.Info("Waiting for consumer goroutine to exit...")
-	c.wg.Wait()
-	c.logger.Info("Consumer goroutine finished.")
+	c.logger.Info("Waiting for in-flight deliveries to finish...", "drainTimeout", c.cfg.DrainTimeout)
+	if !waitTimeout(c.wg, c.cfg.DrainTimeout) {
+		c.logger.Warn("Drain timed out, cancelling in-flight deliveries", "drainTimeout", c.cfg.DrainTimeout)
+		c.abortFunc()
+		c.wg.Wait()
+	}
+	c.abortFunc()
+	c.logger.Info("Consumer workers finished.")
 
 	if err := c.channel.Close(); err != nil {
 		c.logger.Error("Failed to close consumer channel", "error", err)
@@ -149,3 +256,20 @@ func (c *Consumer) Stop() {
 		c.logger.Info("Consumer channel closed.")
 	}
 }
+
+// waitTimeout reports whether wg finished within timeout.
+func waitTimeout(wg *sync.WaitGroup, timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
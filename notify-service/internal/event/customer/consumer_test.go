@@ -0,0 +1,170 @@
+package event
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeChannel hands out the deliveries written to each queue and closes a
+// queue's deliveries when its consumer tag is cancelled, as the broker does.
+type fakeChannel struct {
+	mu     sync.Mutex
+	queues map[string]chan amqp.Delivery
+	tags   map[string]string
+	closed atomic.Bool
+}
+
+func newFakeChannel(queues ...string) *fakeChannel {
+	c := &fakeChannel{queues: map[string]chan amqp.Delivery{}, tags: map[string]string{}}
+	for _, q := range queues {
+		c.queues[q] = make(chan amqp.Delivery)
+	}
+	return c
+}
+
+func (c *fakeChannel) Consume(queue, consumer string, _, _, _, _ bool, _ amqp.Table) (<-chan amqp.Delivery, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tags[consumer] = queue
+	return c.queues[queue], nil
+}
+
+func (c *fakeChannel) Cancel(consumer string, _ bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if q, ok := c.tags[consumer]; ok {
+		close(c.queues[q])
+		delete(c.tags, consumer)
+	}
+	return nil
+}
+
+func (c *fakeChannel) Close() error {
+	c.closed.Store(true)
+	return nil
+}
+
+func TestConsumerWorkerPool(t *testing.T) {
+	discard := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	t.Run("handles deliveries concurrently up to the worker count", func(t *testing.T) {
+		ch := newFakeChannel("q")
+		var running, peak atomic.Int32
+		release := make(chan struct{})
+		started := make(chan struct{}, 3)
+		handler := func(ctx context.Context, d amqp.Delivery) {
+			n := running.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			started <- struct{}{}
+			<-release
+			running.Add(-1)
+		}
+
+		consumer := newConsumer(ch, []string{"q"}, "tag", handler, ConsumerConfig{Workers: 2}, discard)
+		require.NoError(t, consumer.Start(context.Background()))
+		go func() {
+			for i := range 3 {
+				ch.queues["q"] <- amqp.Delivery{DeliveryTag: uint64(i + 1)}
+			}
+		}()
+
+		<-started
+		<-started
+		select {
+		case <-started:
+			t.Fatal("a third delivery was handled while both workers were busy")
+		case <-time.After(50 * time.Millisecond):
+		}
+		close(release)
+		<-started
+
+		consumer.Stop()
+		assert.Equal(t, int32(2), peak.Load())
+	})
+
+	t.Run("stop waits for deliveries in flight", func(t *testing.T) {
+		ch := newFakeChannel("q")
+		var finished atomic.Bool
+		started := make(chan struct{})
+		handler := func(ctx context.Context, d amqp.Delivery) {
+			close(started)
+			time.Sleep(50 * time.Millisecond)
+			finished.Store(ctx.Err() == nil)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		consumer := newConsumer(ch, []string{"q"}, "tag", handler, ConsumerConfig{Workers: 1}, discard)
+		require.NoError(t, consumer.Start(ctx))
+		ch.queues["q"] <- amqp.Delivery{DeliveryTag: 1}
+		<-started
+
+		cancel()
+		consumer.Stop()
+		assert.True(t, finished.Load(), "the handler finished with a live context after the shutdown signal")
+		assert.True(t, ch.closed.Load())
+	})
+
+	t.Run("stop cancels handlers that outlast the drain timeout", func(t *testing.T) {
+		ch := newFakeChannel("q")
+		started := make(chan struct{})
+		handler := func(ctx context.Context, d amqp.Delivery) {
+			close(started)
+			<-ctx.Done()
+		}
+
+		consumer := newConsumer(ch, []string{"q"}, "tag", handler, ConsumerConfig{Workers: 1, DrainTimeout: 20 * time.Millisecond}, discard)
+		require.NoError(t, consumer.Start(context.Background()))
+		ch.queues["q"] <- amqp.Delivery{DeliveryTag: 1}
+		<-started
+
+		consumer.Stop()
+		assert.True(t, ch.closed.Load())
+	})
+}
+
+func TestConsumerReadsEveryQueue(t *testing.T) {
+	discard := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ch := newFakeChannel("notify-service.customer", "notify-service.loan")
+	var mu sync.Mutex
+	var keys []string
+	handled := make(chan struct{}, 2)
+	handler := func(ctx context.Context, d amqp.Delivery) {
+		mu.Lock()
+		keys = append(keys, d.RoutingKey)
+		mu.Unlock()
+		handled <- struct{}{}
+	}
+
+	consumer := newConsumer(ch, []string{"notify-service.customer", "notify-service.loan"}, "tag", handler, ConsumerConfig{Workers: 1}, discard)
+	require.NoError(t, consumer.Start(context.Background()))
+	assert.Equal(t, map[string]string{"tag.notify-service.customer": "notify-service.customer", "tag.notify-service.loan": "notify-service.loan"}, ch.tags)
+
+	ch.queues["notify-service.loan"] <- amqp.Delivery{RoutingKey: "loan.created"}
+	ch.queues["notify-service.customer"] <- amqp.Delivery{RoutingKey: "customer.created"}
+	<-handled
+	<-handled
+
+	consumer.Stop()
+	assert.ElementsMatch(t, []string{"loan.created", "customer.created"}, keys)
+	assert.Empty(t, ch.tags, "every consumer tag is cancelled on stop")
+	assert.True(t, ch.closed.Load())
+}
+
+func TestConsumerConfigDefaults(t *testing.T) {
+	assert.Equal(t, ConsumerConfig{PrefetchCount: 1, Workers: 1, DrainTimeout: defaultDrainTimeout}, ConsumerConfig{}.withDefaults())
+	assert.Equal(t, ConsumerConfig{PrefetchCount: 4, Workers: 4, DrainTimeout: time.Second}, ConsumerConfig{Workers: 4, DrainTimeout: time.Second}.withDefaults())
+}
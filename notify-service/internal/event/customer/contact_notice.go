@@ -0,0 +1,72 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"notify-service/internal/domain/customer"
+	"notify-service/internal/domain/notification"
+	"notify-service/internal/i18n"
+	"time"
+)
+
+// ContactNotifier sends the verification codes billing-engine issues for a
+// customer's new phone numbers and email addresses.
+type ContactNotifier struct {
+	prefs         customer.PreferencesRepository
+	catalog       *i18n.Catalog
+	notifications notification.Service
+	logger        *slog.Logger
+	now           func() time.Time
+}
+
+func NewContactNotifier(notifications notification.Service, logger *slog.Logger) *ContactNotifier {
+	return &ContactNotifier{
+		catalog:       i18n.BuiltIn(),
+		notifications: notifications,
+		logger:        logger.With("component", "ContactNotifier"),
+		now:           time.Now,
+	}
+}
+
+// UsePreferences writes the codes in the customer's locale.
+func (n *ContactNotifier) UsePreferences(prefs customer.PreferencesRepository) {
+	n.prefs = prefs
+}
+
+// UseCatalog replaces the built-in message catalogs.
+func (n *ContactNotifier) UseCatalog(catalog *i18n.Catalog) {
+	n.catalog = catalog
+}
+
+// VerificationRequested sends event's code to the contact being verified,
+// which is not one of the customer's verified contacts yet. A code that
+// expired before it could be sent, or one for a contact type no channel
+// sends to, is dropped: retrying cannot deliver it in time.
+func (n *ContactNotifier) VerificationRequested(ctx context.Context, event CustomerContactVerificationRequestedEvent) error {
+	logCtx := n.logger.With(slog.Int64("customerID", event.CustomerID), slog.Int64("contactID", event.ContactID))
+	validFor := event.ExpiresAt.Sub(n.now())
+	if validFor <= 0 {
+		logCtx.WarnContext(ctx, "Verification code expired before it was sent, dropping", slog.Time("expiresAt", event.ExpiresAt))
+		return nil
+	}
+
+	data := i18n.Data{Code: event.Code, CodeValidMinutes: int(math.Ceil(validFor.Minutes()))}
+	subject, body, err := render(ctx, n.catalog, n.prefs, event.CustomerID, notification.EventContactVerification, data)
+	if err != nil {
+		return err
+	}
+
+	_, err = n.notifications.Notify(ctx, event.CustomerID, notification.EventContactVerification, notification.Message{
+		Channel:   customer.ContactChannel(event.Type),
+		Recipient: event.Value,
+		Subject:   subject,
+		Body:      body,
+	})
+	if errors.Is(err, notification.ErrUnknownChannel) {
+		logCtx.WarnContext(ctx, "No sender for the contact's channel, verification code dropped", slog.String("type", event.Type))
+		return nil
+	}
+	return err
+}
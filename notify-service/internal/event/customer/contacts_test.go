@@ -0,0 +1,141 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"notify-service/internal/domain/customer"
+	"notify-service/internal/domain/notification"
+	"notify-service/internal/domain/retry"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockContactsRepository struct {
+	mock.Mock
+}
+
+func (m *mockContactsRepository) UpsertContacts(ctx context.Context, c *customer.Contacts) error {
+	return m.Called(ctx, c).Error(0)
+}
+
+func (m *mockContactsRepository) FindContacts(ctx context.Context, customerID int64) (*customer.Contacts, error) {
+	args := m.Called(ctx, customerID)
+	c, _ := args.Get(0).(*customer.Contacts)
+	return c, args.Error(1)
+}
+
+func TestProcessContactsChanged(t *testing.T) {
+	ctx := context.Background()
+	discard := slog.New(slog.NewTextHandler(io.Discard, nil))
+	body := []byte(`{"customerId":7,"contacts":[{"contactId":12,"type":"phone","value":"+6281234567890","verified":true,"primary":true}],"updatedAt":"2025-03-05T09:00:00Z"}`)
+
+	t.Run("stores the contacts", func(t *testing.T) {
+		contacts := new(mockContactsRepository)
+		contacts.On("UpsertContacts", ctx, &customer.Contacts{
+			CustomerID: 7,
+			Contacts:   []customer.Contact{{ContactID: 12, Type: customer.ContactPhone, Value: "+6281234567890", Verified: true, Primary: true}},
+			UpdatedAt:  time.Date(2025, 3, 5, 9, 0, 0, 0, time.UTC),
+		}).Return(nil)
+
+		handler := NewCustomerEventHandler(nil, nil, nil, retry.Policy{}, discard)
+		handler.HandleContacts(contacts, nil)
+
+		require.NoError(t, handler.Process(ctx, routingKeyContactsChanged, body))
+		contacts.AssertExpectations(t)
+	})
+
+	t.Run("rejected as unknown without a store", func(t *testing.T) {
+		err := NewCustomerEventHandler(nil, nil, nil, retry.Policy{}, discard).Process(ctx, routingKeyContactsChanged, body)
+
+		assert.ErrorIs(t, err, errUnknownRoutingKey)
+	})
+}
+
+func TestProcessVerificationRequested(t *testing.T) {
+	ctx := context.Background()
+	discard := slog.New(slog.NewTextHandler(io.Discard, nil))
+	body := []byte(`{"customerId":7,"contactId":13,"type":"email","value":"ayu@example.com","code":"482913","expiresAt":"2025-03-05T09:15:00Z"}`)
+
+	t.Run("acknowledged without notices", func(t *testing.T) {
+		handler := NewCustomerEventHandler(nil, nil, nil, retry.Policy{}, discard)
+		handler.HandleContacts(new(mockContactsRepository), nil)
+
+		assert.NoError(t, handler.Process(ctx, routingKeyContactVerificationRequested, body))
+	})
+
+	t.Run("rejected as unknown without contacts", func(t *testing.T) {
+		err := NewCustomerEventHandler(nil, nil, nil, retry.Policy{}, discard).Process(ctx, routingKeyContactVerificationRequested, body)
+
+		assert.ErrorIs(t, err, errUnknownRoutingKey)
+	})
+}
+
+func TestVerificationRequested(t *testing.T) {
+	ctx := context.Background()
+	discard := slog.New(slog.NewTextHandler(io.Discard, nil))
+	sentAt := time.Date(2025, 3, 5, 9, 0, 30, 0, time.UTC)
+	event := CustomerContactVerificationRequestedEvent{CustomerID: 7, ContactID: 13, Type: customer.ContactEmail,
+		Value: "ayu@example.com", Code: "482913", ExpiresAt: time.Date(2025, 3, 5, 9, 15, 0, 0, time.UTC)}
+	newNotifier := func(notifications notification.Service, now time.Time) *ContactNotifier {
+		n := NewContactNotifier(notifications, discard)
+		n.now = func() time.Time { return now }
+		return n
+	}
+
+	t.Run("sends the code to the contact", func(t *testing.T) {
+		notifications := new(mockNotificationService)
+		notifications.On("Notify", ctx, int64(7), notification.EventContactVerification, notification.Message{
+			Channel:   "email",
+			Recipient: "ayu@example.com",
+			Subject:   "Your verification code",
+			Body:      "Your verification code is 482913. It is valid for 15 minutes. Do not share it with anyone.",
+		}).Return(&notification.Notification{}, nil)
+
+		require.NoError(t, newNotifier(notifications, sentAt).VerificationRequested(ctx, event))
+		notifications.AssertExpectations(t)
+	})
+
+	t.Run("in the customer's language", func(t *testing.T) {
+		prefs := new(mockPreferencesRepository)
+		prefs.On("FindPreferences", ctx, int64(7)).Return(&customer.Preferences{CustomerID: 7, Language: "id"}, nil)
+		notifications := new(mockNotificationService)
+		notifications.On("Notify", ctx, int64(7), notification.EventContactVerification, mock.MatchedBy(func(msg notification.Message) bool {
+			return msg.Subject == "Kode verifikasi Anda"
+		})).Return(&notification.Notification{}, nil)
+		notices := newNotifier(notifications, sentAt)
+		notices.UsePreferences(prefs)
+
+		require.NoError(t, notices.VerificationRequested(ctx, event))
+		notifications.AssertExpectations(t)
+	})
+
+	t.Run("expired codes are dropped", func(t *testing.T) {
+		notifications := new(mockNotificationService)
+
+		require.NoError(t, newNotifier(notifications, event.ExpiresAt).VerificationRequested(ctx, event))
+		notifications.AssertNotCalled(t, "Notify")
+	})
+
+	t.Run("a channel without a sender is not retried", func(t *testing.T) {
+		notifications := new(mockNotificationService)
+		notifications.On("Notify", ctx, int64(7), notification.EventContactVerification, mock.Anything).
+			Return(&notification.Notification{}, fmt.Errorf("%w: email", notification.ErrUnknownChannel))
+
+		assert.NoError(t, newNotifier(notifications, sentAt).VerificationRequested(ctx, event))
+	})
+
+	t.Run("delivery errors are returned for a retry", func(t *testing.T) {
+		notifications := new(mockNotificationService)
+		notifications.On("Notify", ctx, int64(7), notification.EventContactVerification, mock.Anything).
+			Return(&notification.Notification{}, errors.New("smtp timeout"))
+
+		assert.EqualError(t, newNotifier(notifications, sentAt).VerificationRequested(ctx, event), "smtp timeout")
+	})
+}
@@ -0,0 +1,147 @@
+package event
+
+import (
+	"context"
+	"events"
+	"events/contract"
+	"io"
+	"log/slog"
+	"notify-service/internal/config"
+	"notify-service/internal/domain/customer"
+	"notify-service/internal/domain/loan"
+	"notify-service/internal/domain/notification"
+	"notify-service/internal/domain/retry"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// contractMocks are the stores behind a handler with every kind of event
+// attached, so that each example reaches the code that reads it.
+type contractMocks struct {
+	customers     *mockCustomerRepository
+	prefs         *mockPreferencesRepository
+	contacts      *mockContactsRepository
+	loans         *mockLoanRepository
+	notifications *mockNotificationService
+	processed     *mockProcessedRepository
+}
+
+// TestContractExamplesAreHandled feeds every example body billing-engine is
+// held to through the handler, as the consumer would receive it, and checks
+// that the values reach the stores and the messages. An example that no
+// longer decodes, or decodes into zero values, fails here.
+func TestContractExamplesAreHandled(t *testing.T) {
+	ctx := context.Background()
+	discard := slog.New(slog.NewTextHandler(io.Discard, nil))
+	at := func(s string) time.Time {
+		ts, err := time.Parse(time.RFC3339, s)
+		require.NoError(t, err)
+		return ts
+	}
+	loanID := int64(3)
+	ayu := &customer.Customer{CustomerID: 7, Name: "Ayu Lestari", Address: "Jl. Merdeka No. 5, Bandung"}
+	notified := func(m contractMocks, event string, body string) {
+		m.customers.On("FindByID", ctx, int64(7)).Return(ayu, nil)
+		m.notifications.On("Notify", ctx, int64(7), event, mock.MatchedBy(func(msg notification.Message) bool {
+			return msg.Recipient == ayu.Address && strings.Contains(msg.Body, body)
+		})).Return(&notification.Notification{}, nil)
+	}
+
+	expectations := map[string]func(m contractMocks){
+		routingKeyCustomerCreated: func(m contractMocks) {
+			m.customers.On("Upsert", ctx, &customer.Customer{CustomerID: 7, Name: "Ayu Lestari", Address: "Jl. Merdeka No. 5, Bandung",
+				Active: true, LoanID: &loanID, CreatedAt: at("2025-03-04T10:00:00Z"), UpdatedAt: at("2025-03-04T10:00:00Z"), Sequence: 4}).Return(nil)
+		},
+		routingKeyCustomerUpdated: func(m contractMocks) {
+			m.customers.On("Upsert", ctx, &customer.Customer{CustomerID: 7, Name: "Ayu Lestari", Address: "Jl. Sudirman No. 12, Jakarta",
+				IsDelinquent: true, Active: true, LoanID: &loanID, CreatedAt: at("2025-03-04T10:00:00Z"), UpdatedAt: at("2025-03-11T10:00:00Z"), Sequence: 4}).Return(nil)
+		},
+		routingKeyDelinquencyChanged: func(contractMocks) {},
+		routingKeyPreferencesChanged: func(m contractMocks) {
+			m.prefs.On("UpsertPreferences", ctx, &customer.Preferences{CustomerID: 7, PreferredChannel: "email", Language: "id",
+				MarketingOptOut: true, UpdatedAt: at("2025-03-05T08:00:00Z")}).Return(nil)
+		},
+		routingKeyContactsChanged: func(m contractMocks) {
+			m.contacts.On("UpsertContacts", ctx, &customer.Contacts{CustomerID: 7, UpdatedAt: at("2025-03-05T09:00:00Z"), Contacts: []customer.Contact{
+				{ContactID: 12, Type: customer.ContactPhone, Value: "+6281234567890", Verified: true, Primary: true},
+			}}).Return(nil)
+		},
+		routingKeyContactVerificationRequested: func(m contractMocks) {
+			m.notifications.On("Notify", ctx, int64(7), notification.EventContactVerification, mock.MatchedBy(func(msg notification.Message) bool {
+				return msg.Channel == "email" && msg.Recipient == "ayu.lestari@example.com" && strings.Contains(msg.Body, "482913")
+			})).Return(&notification.Notification{}, nil)
+		},
+		routingKeyLoanCreated: func(m contractMocks) {
+			m.loans.On("Create", ctx, mock.MatchedBy(func(l *loan.Loan) bool {
+				return l.LoanID == 3 && l.CustomerID == 7 && l.PrincipalAmount == 5000000 && l.TermWeeks == 50 && l.CreatedAt.Equal(at("2025-03-04T10:00:00Z"))
+			})).Return(nil)
+			notified(m, notification.EventLoanConfirmation, "loan 3 of 5,000,000.00 over 50 weeks")
+		},
+		routingKeyLoanPaymentReceived: func(m contractMocks) {
+			m.loans.On("RecordPayment", ctx, int64(3), 110000.0, at("2025-03-11T09:30:00Z")).
+				Return(&loan.Loan{LoanID: 3, CustomerID: 7, AmountPaid: 110000}, nil)
+			notified(m, notification.EventPaymentReceipt, "payment of 110,000.00 for loan 3")
+		},
+		routingKeyLoanDelinquent: func(m contractMocks) {
+			m.loans.On("UpdateStatus", ctx, int64(3), loan.StatusDelinquent, at("2025-03-26T01:00:00Z")).Return(&loan.Loan{LoanID: 3}, nil)
+			notified(m, notification.EventDelinquencyNotice, "loan 3 is 15 days past due")
+		},
+		routingKeyLoanPaidOff: func(m contractMocks) {
+			m.loans.On("UpdateStatus", ctx, int64(3), loan.StatusPaidOff, at("2026-02-10T09:30:00Z")).
+				Return(&loan.Loan{LoanID: 3, CustomerID: 7, Status: loan.StatusPaidOff}, nil)
+			notified(m, notification.EventLoanPaidOff, "")
+		},
+		routingKeyLoanReminderDue: func(m contractMocks) {
+			m.customers.On("FindByID", ctx, int64(7)).Return(ayu, nil)
+			m.notifications.On("Notify", ctx, int64(7), notification.EventPaymentReminder, mock.MatchedBy(func(msg notification.Message) bool {
+				return msg.Channel == "email" && strings.Contains(msg.Body, "loan 3 is 8 days past due")
+			})).Return(&notification.Notification{}, nil)
+		},
+	}
+
+	var subscribed []string
+	for _, q := range config.DefaultTopology("billing-engine").Queues {
+		for _, b := range q.Bindings {
+			subscribed = append(subscribed, b.RoutingKeys...)
+		}
+	}
+	slices.Sort(subscribed)
+	assert.Equal(t, slices.Compact(subscribed), contract.RoutingKeys(), "every routing key the queues subscribe to needs an example, and no other")
+
+	for _, key := range contract.RoutingKeys() {
+		t.Run(key, func(t *testing.T) {
+			expect, ok := expectations[key]
+			require.True(t, ok, "no expectations for %s", key)
+			body, _ := contract.Body(key)
+
+			m := contractMocks{new(mockCustomerRepository), new(mockPreferencesRepository), new(mockContactsRepository), new(mockLoanRepository), new(mockNotificationService), new(mockProcessedRepository)}
+			eventID := events.EventID(body)
+			m.processed.On("IsProcessed", ctx, eventID).Return(false, nil)
+			m.processed.On("MarkProcessed", ctx, eventID, key).Return(nil)
+			expect(m)
+
+			notices := NewLoanNotifier(m.customers, m.notifications, "log")
+			notices.UseChannels([]string{"log", "email"})
+			contactNotices := NewContactNotifier(m.notifications, discard)
+			contactNotices.now = func() time.Time { return at("2025-03-05T09:00:00Z") }
+			handler := NewCustomerEventHandler(m.customers, m.processed, nil, retry.Policy{}, discard)
+			handler.HandlePreferences(m.prefs)
+			handler.HandleContacts(m.contacts, contactNotices)
+			handler.HandleLoanEvents(NewLoanEventHandler(m.loans, notices, discard))
+
+			require.NoError(t, handler.Process(ctx, key, body))
+			m.customers.AssertExpectations(t)
+			m.prefs.AssertExpectations(t)
+			m.contacts.AssertExpectations(t)
+			m.loans.AssertExpectations(t)
+			m.notifications.AssertExpectations(t)
+			m.processed.AssertExpectations(t)
+		})
+	}
+}
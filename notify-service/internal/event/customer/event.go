@@ -1,24 +1,16 @@
 package event
 
-import "time"
+import "events"
 
-type CustomerEventPayload struct {
-	CustomerID   int64     `json:"customerId"`
-	Name         string    `json:"name"`
-	Address      string    `json:"address"`
-	IsDelinquent bool      `json:"isDelinquent"`
-	Active       bool      `json:"active"`
-	LoanID       *int64    `json:"loanId,omitempty"`
-	CreateDate   time.Time `json:"createDate"`
-	UpdatedAt    time.Time `json:"updatedAt"`
-}
+// The message bodies are defined in the shared events module, which
+// billing-engine publishes from; the names here keep the handlers readable.
+type (
+	CustomerEventPayload            = events.CustomerEventPayload
+	CustomerCreatedEvent            = events.CustomerCreatedEvent
+	CustomerUpdatedEvent            = events.CustomerUpdatedEvent
+	CustomerDelinquencyChangedEvent = events.CustomerDelinquencyChangedEvent
+	CustomerPreferencesChangedEvent = events.CustomerPreferencesChangedEvent
 
-type CustomerCreatedEvent struct {
-	Timestamp time.Time            `json:"timestamp"`
-	Payload   CustomerEventPayload `json:"payload"`
-}
-
-type CustomerUpdatedEvent struct {
-	Timestamp time.Time            `json:"timestamp"`
-	Payload   CustomerEventPayload `json:"payload"`
-}
+	CustomerContactsChangedEvent              = events.CustomerContactsChangedEvent
+	CustomerContactVerificationRequestedEvent = events.CustomerContactVerificationRequestedEvent
+)
@@ -2,26 +2,85 @@ package event
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"events"
+	"fmt"
 	"log/slog"
 	"notify-service/internal/domain/customer"
+	"notify-service/internal/domain/idempotency"
+	"notify-service/internal/domain/retry"
+	"notify-service/internal/infrastructure/errorreport"
 	"notify-service/internal/infrastructure/monitoring"
+	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
+var (
+	// errUnknownRoutingKey and errMalformedEvent are permanent: running the
+	// same message again cannot succeed, so it is never retried.
+	errUnknownRoutingKey = events.ErrUnknownRoutingKey
+	errMalformedEvent    = events.ErrMalformed
+)
+
 type CustomerEventHandler struct {
-	repo   customer.CustomerRepository
-	logger *slog.Logger
+	repo      customer.CustomerRepository
+	processed idempotency.Repository
+	retries   retry.Repository
+	policy    retry.Policy
+	loans     *LoanEventHandler
+	prefs     customer.PreferencesRepository
+	contacts  customer.ContactsRepository
+	notices   *ContactNotifier
+	reporter  errorreport.Reporter
+	logger    *slog.Logger
+	now       func() time.Time
 }
 
-func NewCustomerEventHandler(repo customer.CustomerRepository, logger *slog.Logger) *CustomerEventHandler {
+// NewCustomerEventHandler builds the handler. With a nil processed store
+// every delivery is applied, duplicates included. With a nil retries store a
+// failed delivery is dropped, as before the retry store existed.
+func NewCustomerEventHandler(repo customer.CustomerRepository, processed idempotency.Repository, retries retry.Repository, policy retry.Policy, logger *slog.Logger) *CustomerEventHandler {
 	return &CustomerEventHandler{
-		repo:   repo,
-		logger: logger.With("component", "CustomerEventHandler"),
+		repo:      repo,
+		processed: processed,
+		retries:   retries,
+		policy:    policy,
+		logger:    logger.With("component", "CustomerEventHandler"),
+		now:       time.Now,
 	}
 }
 
+// HandleLoanEvents routes the loan.* events to loans, which then get the
+// same deduplication and retries as customer events. Without it they are
+// rejected as unknown.
+func (h *CustomerEventHandler) HandleLoanEvents(loans *LoanEventHandler) {
+	h.loans = loans
+}
+
+// HandlePreferences stores customer.preferences.changed events in prefs.
+// Without it they are rejected as unknown.
+func (h *CustomerEventHandler) HandlePreferences(prefs customer.PreferencesRepository) {
+	h.prefs = prefs
+}
+
+// HandleContacts stores customer.contacts.changed events in contacts and
+// sends the codes of customer.contact.verification_requested events through
+// notices. Without it contact changes are rejected as unknown; with a nil
+// notices the codes are acknowledged without being sent, as when
+// notifications are disabled.
+func (h *CustomerEventHandler) HandleContacts(contacts customer.ContactsRepository, notices *ContactNotifier) {
+	h.contacts = contacts
+	h.notices = notices
+}
+
+// ReportErrors sends deliveries that fail for a transient reason to
+// reporter, tagged with their routing key. Those are only logged as
+// warnings, being retried, so the error-level log hook does not see them.
+func (h *CustomerEventHandler) ReportErrors(reporter errorreport.Reporter) {
+	h.reporter = reporter
+}
+
 func (h *CustomerEventHandler) HandleDelivery(ctx context.Context, d amqp.Delivery) {
 	logCtx := h.logger.With(slog.Uint64("deliveryTag", d.DeliveryTag), slog.String("routingKey", d.RoutingKey))
 	processed := false
@@ -33,32 +92,155 @@ func (h *CustomerEventHandler) HandleDelivery(ctx context.Context, d amqp.Delive
 		}
 	}()
 
+	err := h.Process(ctx, d.RoutingKey, d.Body)
+	switch {
+	case err == nil:
+	case errors.Is(err, errUnknownRoutingKey):
+		logCtx.WarnContext(ctx, "Received message with unknown routing key. Discarding.")
+		_ = d.Reject(false)
+		processed = true
+		return
+	case errors.Is(err, errMalformedEvent):
+		logCtx.ErrorContext(ctx, "Failed to unmarshal event", "error", err, "body", string(d.Body))
+		_ = d.Nack(false, false)
+		processed = true
+		return
+	default:
+		h.report(ctx, logCtx, d, err)
+		h.scheduleRetry(ctx, logCtx, d, err)
+		processed = true
+		return
+	}
+
+	if err := d.Ack(false); err != nil {
+		logCtx.ErrorContext(ctx, "Failed to acknowledge message after successful processing", "error", err)
+
+	} else {
+		logCtx.InfoContext(ctx, "Successfully processed and acknowledged message")
+	}
+	processed = true
+}
+
+// report sends cause to the reporter in the background, so that the worker
+// is not held up by Sentry.
+func (h *CustomerEventHandler) report(ctx context.Context, logCtx *slog.Logger, d amqp.Delivery, cause error) {
+	if h.reporter == nil {
+		return
+	}
+	event := errorreport.Event{
+		Message: "Processing failed: " + cause.Error(),
+		Time:    h.now(),
+		Tags:    map[string]string{"routingKey": d.RoutingKey},
+	}
+	if eventID := events.EventID(d.Body); eventID != "" {
+		event.Tags["eventId"] = eventID
+	}
+	go func() {
+		if err := h.reporter.Report(context.WithoutCancel(ctx), event); err != nil {
+			logCtx.WarnContext(ctx, "Failed to report processing error", "error", err)
+		}
+	}()
+}
+
+// scheduleRetry moves a delivery that failed for a transient reason into the
+// retry store and acknowledges it. If the store cannot take it either, the
+// message goes back to the broker so that it is not lost.
+func (h *CustomerEventHandler) scheduleRetry(ctx context.Context, logCtx *slog.Logger, d amqp.Delivery, cause error) {
+	if h.retries == nil {
+		_ = d.Nack(false, false)
+		return
+	}
+
+	next, _ := h.policy.Next(h.now(), 1)
+	if err := h.retries.Enqueue(ctx, d.RoutingKey, d.Body, cause.Error(), next); err != nil {
+		logCtx.ErrorContext(ctx, "Failed to store retry, requeueing message", "error", err)
+		_ = d.Nack(false, true)
+		return
+	}
+	monitoring.RecordRetry("enqueued")
+	logCtx.WarnContext(ctx, "Processing failed, scheduled retry", "error", cause, "nextAttemptAt", next)
+
+	if err := d.Ack(false); err != nil {
+		logCtx.ErrorContext(ctx, "Failed to acknowledge message after scheduling retry", "error", err)
+	}
+}
+
+// Process decodes one customer or loan event and applies it unless an event with the
+// same ID was already processed. It serves both live deliveries and retries
+// from the store.
+//
+// The ID is recorded after the event is applied, so a crash in between still
+// applies it twice. The customer upsert ignores the stale copy, but a
+// reminder can go out again.
+func (h *CustomerEventHandler) Process(ctx context.Context, routingKey string, body []byte) error {
+	eventID := events.EventID(body)
+	if h.processed == nil {
+		eventID = ""
+	}
+
+	if eventID != "" {
+		done, err := h.processed.IsProcessed(ctx, eventID)
+		if err != nil {
+			return err
+		}
+		if done {
+			monitoring.RecordDuplicateEvent()
+			h.logger.InfoContext(ctx, "Skipping already processed event", slog.String("routingKey", routingKey), slog.String("eventID", eventID))
+			return nil
+		}
+	}
+
+	if err := h.apply(ctx, routingKey, body); err != nil {
+		return err
+	}
+
+	if eventID != "" {
+		if err := h.processed.MarkProcessed(ctx, eventID, routingKey); err != nil {
+			// The event is applied; failing now would only apply it again.
+			h.logger.WarnContext(ctx, "Processed event could not be recorded", slog.String("eventID", eventID), "error", err)
+		}
+	}
+	return nil
+}
+
+func (h *CustomerEventHandler) apply(ctx context.Context, routingKey string, body []byte) error {
+	if h.loans != nil && isLoanRoutingKey(routingKey) {
+		return h.loans.Apply(ctx, routingKey, body)
+	}
+
+	decoded, err := events.Decode(routingKey, body)
+	if err != nil {
+		return err
+	}
+
 	var payload CustomerEventPayload
+	var sequence int64
 
-	switch d.RoutingKey {
-	case routingKeyCustomerCreated:
-		var event CustomerCreatedEvent
-		if err := json.Unmarshal(d.Body, &event); err != nil {
-			logCtx.ErrorContext(ctx, "Failed to unmarshal CustomerCreatedEvent", "error", err, "body", string(d.Body))
-			_ = d.Nack(false, false)
-			processed = true
-			return
+	switch event := decoded.(type) {
+	case *CustomerCreatedEvent:
+		payload, sequence = event.Payload, event.Sequence
+	case *CustomerUpdatedEvent:
+		payload, sequence = event.Payload, event.Sequence
+	case *CustomerDelinquencyChangedEvent:
+		return h.processDelinquencyChanged(ctx, *event)
+	case *CustomerPreferencesChangedEvent:
+		if h.prefs != nil {
+			return h.processPreferencesChanged(ctx, *event)
 		}
-		payload = event.Payload
-	case routingKeyCustomerUpdated:
-		var event CustomerUpdatedEvent
-		if err := json.Unmarshal(d.Body, &event); err != nil {
-			logCtx.ErrorContext(ctx, "Failed to unmarshal CustomerUpdatedEvent", "error", err, "body", string(d.Body))
-			_ = d.Nack(false, false)
-			processed = true
-			return
+		return fmt.Errorf("%w: %s", errUnknownRoutingKey, routingKey)
+	case *CustomerContactsChangedEvent:
+		if h.contacts != nil {
+			return h.processContactsChanged(ctx, *event)
 		}
-		payload = event.Payload
+		return fmt.Errorf("%w: %s", errUnknownRoutingKey, routingKey)
+	case *CustomerContactVerificationRequestedEvent:
+		if h.contacts != nil {
+			return h.processVerificationRequested(ctx, *event)
+		}
+		return fmt.Errorf("%w: %s", errUnknownRoutingKey, routingKey)
 	default:
-		logCtx.WarnContext(ctx, "Received message with unknown routing key. Discarding.")
-		_ = d.Reject(false)
-		processed = true
-		return
+		// A loan event while no loan handler is attached.
+		return fmt.Errorf("%w: %s", errUnknownRoutingKey, routingKey)
 	}
 
 	var customerToUpsert *customer.Customer = &customer.Customer{
@@ -70,24 +252,71 @@ func (h *CustomerEventHandler) HandleDelivery(ctx context.Context, d amqp.Delive
 		LoanID:       payload.LoanID,
 		CreatedAt:    payload.CreateDate,
 		UpdatedAt:    payload.UpdatedAt,
+		Sequence:     sequence,
 	}
 
-	logCtx = logCtx.With(slog.Int64("customerID", customerToUpsert.CustomerID))
+	logCtx := h.logger.With(slog.String("routingKey", routingKey), slog.Int64("customerID", customerToUpsert.CustomerID))
 	logCtx.InfoContext(ctx, "Processing event for customer")
 	monitoring.RecordConsumerProcessed()
 	if err := h.repo.Upsert(ctx, customerToUpsert); err != nil {
 		logCtx.ErrorContext(ctx, "Failed to upsert customer via repository", "error", err)
+		return err
+	}
+	return nil
+}
 
-		_ = d.Nack(false, false)
-		processed = true
-		return
+// processDelinquencyChanged only acknowledges the change. The customer
+// hears about a late payment from the reminder ladder, see
+// LoanEventHandler.reminderDue.
+func (h *CustomerEventHandler) processDelinquencyChanged(ctx context.Context, event CustomerDelinquencyChangedEvent) error {
+	monitoring.RecordConsumerProcessed()
+	h.logger.DebugContext(ctx, "Delinquency change acknowledged", slog.Int64("customerID", event.CustomerID), slog.Bool("newStatus", event.NewStatus))
+	return nil
+}
+
+func (h *CustomerEventHandler) processPreferencesChanged(ctx context.Context, event CustomerPreferencesChangedEvent) error {
+	logCtx := h.logger.With(slog.String("routingKey", routingKeyPreferencesChanged), slog.Int64("customerID", event.CustomerID))
+	monitoring.RecordConsumerProcessed()
+	prefs := &customer.Preferences{
+		CustomerID:          event.CustomerID,
+		PreferredChannel:    event.PreferredChannel,
+		Language:            event.Language,
+		MarketingOptOut:     event.MarketingOptOut,
+		TransactionalOptOut: event.TransactionalOptOut,
+		UpdatedAt:           event.UpdatedAt,
+	}
+	if err := h.prefs.UpsertPreferences(ctx, prefs); err != nil {
+		logCtx.ErrorContext(ctx, "Failed to store customer preferences", "error", err)
+		return err
 	}
+	return nil
+}
 
-	if err := d.Ack(false); err != nil {
-		logCtx.ErrorContext(ctx, "Failed to acknowledge message after successful processing", "error", err)
+func (h *CustomerEventHandler) processContactsChanged(ctx context.Context, event CustomerContactsChangedEvent) error {
+	logCtx := h.logger.With(slog.String("routingKey", routingKeyContactsChanged), slog.Int64("customerID", event.CustomerID))
+	monitoring.RecordConsumerProcessed()
+	contacts := &customer.Contacts{CustomerID: event.CustomerID, UpdatedAt: event.UpdatedAt}
+	for _, c := range event.Contacts {
+		contacts.Contacts = append(contacts.Contacts, customer.Contact{
+			ContactID: c.ContactID,
+			Type:      c.Type,
+			Value:     c.Value,
+			Verified:  c.Verified,
+			Primary:   c.Primary,
+		})
+	}
+	if err := h.contacts.UpsertContacts(ctx, contacts); err != nil {
+		logCtx.ErrorContext(ctx, "Failed to store customer contacts", "error", err)
+		return err
+	}
+	return nil
+}
 
-	} else {
-		logCtx.InfoContext(ctx, "Successfully processed and acknowledged message")
+func (h *CustomerEventHandler) processVerificationRequested(ctx context.Context, event CustomerContactVerificationRequestedEvent) error {
+	monitoring.RecordConsumerProcessed()
+	if h.notices == nil {
+		h.logger.InfoContext(ctx, "Notifications disabled, verification code not sent", slog.Int64("customerID", event.CustomerID), slog.Int64("contactID", event.ContactID))
+		return nil
 	}
-	processed = true
+	return h.notices.VerificationRequested(ctx, event)
 }
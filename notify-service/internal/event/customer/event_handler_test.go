@@ -0,0 +1,148 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"notify-service/internal/domain/customer"
+	"notify-service/internal/domain/retry"
+	"notify-service/internal/infrastructure/errorreport"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockProcessedRepository struct {
+	mock.Mock
+}
+
+func (m *mockProcessedRepository) IsProcessed(ctx context.Context, eventID string) (bool, error) {
+	args := m.Called(ctx, eventID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockProcessedRepository) MarkProcessed(ctx context.Context, eventID, routingKey string) error {
+	return m.Called(ctx, eventID, routingKey).Error(0)
+}
+
+func TestProcessIdempotency(t *testing.T) {
+	ctx := context.Background()
+	discard := slog.New(slog.NewTextHandler(io.Discard, nil))
+	body := []byte(`{"eventId":"evt-1","payload":{"customerId":7,"name":"John Doe"}}`)
+
+	t.Run("applies and records a new event", func(t *testing.T) {
+		customers := new(mockCustomerRepository)
+		customers.On("Upsert", ctx, mock.Anything).Return(nil)
+		processed := new(mockProcessedRepository)
+		processed.On("IsProcessed", ctx, "evt-1").Return(false, nil)
+		processed.On("MarkProcessed", ctx, "evt-1", routingKeyCustomerCreated).Return(nil)
+
+		err := NewCustomerEventHandler(customers, processed, nil, retry.Policy{}, discard).Process(ctx, routingKeyCustomerCreated, body)
+
+		assert.NoError(t, err)
+		customers.AssertExpectations(t)
+		processed.AssertExpectations(t)
+	})
+
+	t.Run("skips a redelivered event", func(t *testing.T) {
+		customers := new(mockCustomerRepository)
+		processed := new(mockProcessedRepository)
+		processed.On("IsProcessed", ctx, "evt-1").Return(true, nil)
+
+		err := NewCustomerEventHandler(customers, processed, nil, retry.Policy{}, discard).Process(ctx, routingKeyCustomerCreated, body)
+
+		assert.NoError(t, err)
+		customers.AssertNotCalled(t, "Upsert", mock.Anything, mock.Anything)
+		processed.AssertNotCalled(t, "MarkProcessed", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("does not record a failed event", func(t *testing.T) {
+		customers := new(mockCustomerRepository)
+		customers.On("Upsert", ctx, mock.Anything).Return(errors.New("db down"))
+		processed := new(mockProcessedRepository)
+		processed.On("IsProcessed", ctx, "evt-1").Return(false, nil)
+
+		err := NewCustomerEventHandler(customers, processed, nil, retry.Policy{}, discard).Process(ctx, routingKeyCustomerCreated, body)
+
+		assert.EqualError(t, err, "db down")
+		processed.AssertNotCalled(t, "MarkProcessed", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("applies events without an ID", func(t *testing.T) {
+		customers := new(mockCustomerRepository)
+		customers.On("Upsert", ctx, mock.Anything).Return(nil)
+		processed := new(mockProcessedRepository)
+
+		err := NewCustomerEventHandler(customers, processed, nil, retry.Policy{}, discard).
+			Process(ctx, routingKeyCustomerUpdated, []byte(`{"payload":{"customerId":7}}`))
+
+		assert.NoError(t, err)
+		processed.AssertNotCalled(t, "IsProcessed", mock.Anything, mock.Anything)
+	})
+
+	t.Run("passes the event's sequence to the upsert", func(t *testing.T) {
+		customers := new(mockCustomerRepository)
+		customers.On("Upsert", ctx, mock.MatchedBy(func(c *customer.Customer) bool {
+			return c.CustomerID == 7 && c.Sequence == 3
+		})).Return(nil)
+
+		err := NewCustomerEventHandler(customers, nil, nil, retry.Policy{}, discard).
+			Process(ctx, routingKeyCustomerUpdated, []byte(`{"sequence":3,"payload":{"customerId":7}}`))
+
+		assert.NoError(t, err)
+		customers.AssertExpectations(t)
+	})
+
+	t.Run("fails when the lookup fails", func(t *testing.T) {
+		processed := new(mockProcessedRepository)
+		processed.On("IsProcessed", ctx, "evt-1").Return(false, errors.New("db down"))
+
+		err := NewCustomerEventHandler(nil, processed, nil, retry.Policy{}, discard).Process(ctx, routingKeyCustomerCreated, body)
+
+		assert.EqualError(t, err, "db down")
+	})
+}
+
+type nackCounter struct{ nacks int }
+
+func (a *nackCounter) Ack(uint64, bool) error        { return nil }
+func (a *nackCounter) Nack(uint64, bool, bool) error { a.nacks++; return nil }
+func (a *nackCounter) Reject(uint64, bool) error     { return nil }
+
+type reporterFunc func(context.Context, errorreport.Event) error
+
+func (f reporterFunc) Report(ctx context.Context, event errorreport.Event) error {
+	return f(ctx, event)
+}
+
+func TestHandleDeliveryReportsFailures(t *testing.T) {
+	ctx := context.Background()
+	customers := new(mockCustomerRepository)
+	customers.On("Upsert", ctx, mock.Anything).Return(errors.New("db down"))
+	reported := make(chan errorreport.Event, 1)
+	handler := NewCustomerEventHandler(customers, nil, nil, retry.Policy{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	handler.ReportErrors(reporterFunc(func(_ context.Context, event errorreport.Event) error {
+		reported <- event
+		return nil
+	}))
+	acks := &nackCounter{}
+
+	handler.HandleDelivery(ctx, amqp.Delivery{
+		Acknowledger: acks,
+		RoutingKey:   routingKeyCustomerCreated,
+		Body:         []byte(`{"eventId":"evt-1","payload":{"customerId":7}}`),
+	})
+
+	select {
+	case event := <-reported:
+		assert.Equal(t, "Processing failed: db down", event.Message)
+		assert.Equal(t, map[string]string{"routingKey": routingKeyCustomerCreated, "eventId": "evt-1"}, event.Tags)
+	case <-time.After(time.Second):
+		t.Fatal("the failure was not reported")
+	}
+	assert.Equal(t, 1, acks.nacks)
+}
@@ -0,0 +1,168 @@
+package event
+
+import (
+	"context"
+	"events"
+	"fmt"
+	"log/slog"
+	"notify-service/internal/domain/loan"
+	"notify-service/internal/infrastructure/monitoring"
+)
+
+// LoanEventHandler keeps the local loan read model up to date and sends the
+// loan notifications. It applies one event at a time; deduplication and
+// retries are left to the CustomerEventHandler it is attached to, since both
+// share the queue.
+type LoanEventHandler struct {
+	repo    loan.Repository
+	notices *LoanNotifier
+	logger  *slog.Logger
+}
+
+// NewLoanEventHandler builds the handler. With nil notices the read model is
+// updated without sending anything.
+func NewLoanEventHandler(repo loan.Repository, notices *LoanNotifier, logger *slog.Logger) *LoanEventHandler {
+	if repo == nil {
+		panic("loan repository cannot be nil")
+	}
+	return &LoanEventHandler{
+		repo:    repo,
+		notices: notices,
+		logger:  logger.With("component", "LoanEventHandler"),
+	}
+}
+
+func isLoanRoutingKey(routingKey string) bool {
+	switch routingKey {
+	case routingKeyLoanCreated, routingKeyLoanPaymentReceived, routingKeyLoanDelinquent, routingKeyLoanPaidOff, routingKeyLoanReminderDue:
+		return true
+	}
+	return false
+}
+
+// Apply decodes one loan event and applies it. Every step is safe to run
+// again except the payment total, see paymentReceived.
+func (h *LoanEventHandler) Apply(ctx context.Context, routingKey string, body []byte) error {
+	decoded, err := events.Decode(routingKey, body)
+	if err != nil {
+		return err
+	}
+	switch event := decoded.(type) {
+	case *LoanCreatedEvent:
+		return h.loanCreated(ctx, *event)
+	case *LoanPaymentReceivedEvent:
+		return h.paymentReceived(ctx, *event)
+	case *LoanDelinquentEvent:
+		return h.loanDelinquent(ctx, *event)
+	case *LoanPaidOffEvent:
+		return h.loanPaidOff(ctx, *event)
+	case *LoanReminderDueEvent:
+		return h.reminderDue(ctx, *event)
+	default:
+		return fmt.Errorf("%w: %s", errUnknownRoutingKey, routingKey)
+	}
+}
+
+func (h *LoanEventHandler) loanCreated(ctx context.Context, event LoanCreatedEvent) error {
+	logCtx := h.logger.With(slog.String("routingKey", routingKeyLoanCreated), slog.Int64("loanID", event.LoanID))
+	monitoring.RecordConsumerProcessed()
+	l := &loan.Loan{
+		LoanID:          event.LoanID,
+		CustomerID:      event.CustomerID,
+		PrincipalAmount: event.PrincipalAmount,
+		TermWeeks:       event.TermWeeks,
+		Status:          loan.StatusActive,
+		StatusChangedAt: event.Timestamp,
+		CreatedAt:       event.Timestamp,
+		UpdatedAt:       event.Timestamp,
+	}
+	if err := h.repo.Create(ctx, l); err != nil {
+		logCtx.ErrorContext(ctx, "Failed to store loan", "error", err)
+		return err
+	}
+	if h.notices == nil {
+		return nil
+	}
+	if err := h.notices.LoanCreated(ctx, l); err != nil {
+		logCtx.ErrorContext(ctx, "Failed to send loan confirmation", "error", err)
+		return err
+	}
+	return nil
+}
+
+// paymentReceived adds the payment before sending the receipt. A receipt that
+// cannot be sent is only logged: retrying the event would count the payment a
+// second time. The failed attempt is still in the notification log.
+func (h *LoanEventHandler) paymentReceived(ctx context.Context, event LoanPaymentReceivedEvent) error {
+	logCtx := h.logger.With(slog.String("routingKey", routingKeyLoanPaymentReceived), slog.Int64("loanID", event.LoanID))
+	monitoring.RecordConsumerProcessed()
+	l, err := h.repo.RecordPayment(ctx, event.LoanID, event.Amount, event.Timestamp)
+	if err != nil {
+		logCtx.ErrorContext(ctx, "Failed to record loan payment", "error", err)
+		return err
+	}
+	if h.notices == nil {
+		return nil
+	}
+	if err := h.notices.PaymentReceived(ctx, l, event.Amount); err != nil {
+		logCtx.WarnContext(ctx, "Failed to send payment receipt", "error", err)
+	}
+	return nil
+}
+
+// loanDelinquent marks the loan delinquent and sends the delinquency notice.
+// billing-engine raises the event only when the customer is flagged or the
+// loan moves to a later DPD bucket, so every event is worth a notice; the
+// reminders in between arrive as loan.reminder.due. Marking the loan again
+// is harmless, so a notice that fails is retried.
+func (h *LoanEventHandler) loanDelinquent(ctx context.Context, event LoanDelinquentEvent) error {
+	logCtx := h.logger.With(slog.String("routingKey", routingKeyLoanDelinquent), slog.Int64("loanID", event.LoanID))
+	monitoring.RecordConsumerProcessed()
+	if _, err := h.repo.UpdateStatus(ctx, event.LoanID, loan.StatusDelinquent, event.Timestamp); err != nil {
+		logCtx.ErrorContext(ctx, "Failed to mark loan delinquent", "error", err)
+		return err
+	}
+	if h.notices == nil {
+		return nil
+	}
+	if err := h.notices.DelinquencyNotice(ctx, event); err != nil {
+		logCtx.ErrorContext(ctx, "Failed to send delinquency notice", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (h *LoanEventHandler) loanPaidOff(ctx context.Context, event LoanPaidOffEvent) error {
+	logCtx := h.logger.With(slog.String("routingKey", routingKeyLoanPaidOff), slog.Int64("loanID", event.LoanID))
+	monitoring.RecordConsumerProcessed()
+	l, err := h.repo.UpdateStatus(ctx, event.LoanID, loan.StatusPaidOff, event.Timestamp)
+	if err != nil {
+		logCtx.ErrorContext(ctx, "Failed to mark loan paid off", "error", err)
+		return err
+	}
+	if h.notices == nil || l.Status != loan.StatusPaidOff {
+		logCtx.DebugContext(ctx, "No paid off notice required", slog.String("status", string(l.Status)))
+		return nil
+	}
+	if err := h.notices.LoanPaidOff(ctx, l, event.DocumentURL); err != nil {
+		logCtx.ErrorContext(ctx, "Failed to send paid off notice", "error", err)
+		return err
+	}
+	return nil
+}
+
+// reminderDue sends the step of the reminder ladder the loan reached. It
+// does not touch the read model, so a reminder that fails is retried.
+func (h *LoanEventHandler) reminderDue(ctx context.Context, event LoanReminderDueEvent) error {
+	logCtx := h.logger.With(slog.String("routingKey", routingKeyLoanReminderDue), slog.Int64("loanID", event.LoanID))
+	monitoring.RecordConsumerProcessed()
+	if h.notices == nil {
+		logCtx.DebugContext(ctx, "No reminder sent, notifications are disabled", slog.Int("step", event.Step))
+		return nil
+	}
+	if err := h.notices.PaymentReminder(ctx, event); err != nil {
+		logCtx.ErrorContext(ctx, "Failed to send payment reminder", "error", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,238 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"notify-service/internal/domain/customer"
+	"notify-service/internal/domain/loan"
+	"notify-service/internal/domain/notification"
+	"notify-service/internal/domain/retry"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockLoanRepository struct {
+	mock.Mock
+}
+
+func (m *mockLoanRepository) Create(ctx context.Context, l *loan.Loan) error {
+	return m.Called(ctx, l).Error(0)
+}
+
+func (m *mockLoanRepository) RecordPayment(ctx context.Context, loanID int64, amount float64, at time.Time) (*loan.Loan, error) {
+	args := m.Called(ctx, loanID, amount, at)
+	l, _ := args.Get(0).(*loan.Loan)
+	return l, args.Error(1)
+}
+
+func (m *mockLoanRepository) UpdateStatus(ctx context.Context, loanID int64, status loan.Status, at time.Time) (*loan.Loan, error) {
+	args := m.Called(ctx, loanID, status, at)
+	l, _ := args.Get(0).(*loan.Loan)
+	return l, args.Error(1)
+}
+
+func (m *mockLoanRepository) FindByID(ctx context.Context, loanID int64) (*loan.Loan, error) {
+	args := m.Called(ctx, loanID)
+	l, _ := args.Get(0).(*loan.Loan)
+	return l, args.Error(1)
+}
+
+func TestLoanEventHandler(t *testing.T) {
+	ctx := context.Background()
+	discard := slog.New(slog.NewTextHandler(io.Discard, nil))
+	johnDoe := &customer.Customer{CustomerID: 7, Name: "John Doe", Address: "123 Main St"}
+	at := time.Date(2025, 3, 4, 10, 0, 0, 0, time.UTC)
+
+	t.Run("stores a new loan and confirms it", func(t *testing.T) {
+		loans := new(mockLoanRepository)
+		loans.On("Create", ctx, mock.MatchedBy(func(l *loan.Loan) bool {
+			return l.LoanID == 3 && l.CustomerID == 7 && l.PrincipalAmount == 5000000 && l.Status == loan.StatusActive && l.StatusChangedAt.Equal(at)
+		})).Return(nil)
+		customers := new(mockCustomerRepository)
+		customers.On("FindByID", ctx, int64(7)).Return(johnDoe, nil)
+		notifications := new(mockNotificationService)
+		notifications.On("Notify", ctx, int64(7), notification.EventLoanConfirmation, mock.MatchedBy(func(msg notification.Message) bool {
+			return msg.Recipient == "123 Main St" && strings.Contains(msg.Body, "loan 3 of 5,000,000.00 over 50 weeks")
+		})).Return(&notification.Notification{}, nil)
+
+		handler := NewLoanEventHandler(loans, NewLoanNotifier(customers, notifications, "log"), discard)
+		err := handler.Apply(ctx, routingKeyLoanCreated,
+			[]byte(`{"eventId":"evt-1","loanId":3,"customerId":7,"principalAmount":5000000,"termWeeks":50,"timestamp":"2025-03-04T10:00:00Z"}`))
+
+		require.NoError(t, err)
+		loans.AssertExpectations(t)
+		notifications.AssertExpectations(t)
+	})
+
+	t.Run("fails for retry when the customer is not replicated yet", func(t *testing.T) {
+		loans := new(mockLoanRepository)
+		loans.On("Create", ctx, mock.Anything).Return(nil)
+		customers := new(mockCustomerRepository)
+		customers.On("FindByID", ctx, int64(7)).Return(nil, customer.ErrNotFound)
+
+		handler := NewLoanEventHandler(loans, NewLoanNotifier(customers, new(mockNotificationService), "log"), discard)
+		err := handler.Apply(ctx, routingKeyLoanCreated, []byte(`{"loanId":3,"customerId":7}`))
+
+		assert.ErrorIs(t, err, customer.ErrNotFound)
+	})
+
+	t.Run("adds the payment and sends a receipt", func(t *testing.T) {
+		loans := new(mockLoanRepository)
+		loans.On("RecordPayment", ctx, int64(3), 110000.0, at).
+			Return(&loan.Loan{LoanID: 3, CustomerID: 7, AmountPaid: 220000}, nil)
+		customers := new(mockCustomerRepository)
+		customers.On("FindByID", ctx, int64(7)).Return(johnDoe, nil)
+		notifications := new(mockNotificationService)
+		notifications.On("Notify", ctx, int64(7), notification.EventPaymentReceipt, mock.MatchedBy(func(msg notification.Message) bool {
+			return strings.Contains(msg.Body, "payment of 110,000.00 for loan 3") && strings.Contains(msg.Body, "220,000.00 in total")
+		})).Return(&notification.Notification{}, nil)
+
+		handler := NewLoanEventHandler(loans, NewLoanNotifier(customers, notifications, "log"), discard)
+		err := handler.Apply(ctx, routingKeyLoanPaymentReceived, []byte(`{"loanId":3,"amount":110000,"timestamp":"2025-03-04T10:00:00Z"}`))
+
+		require.NoError(t, err)
+		notifications.AssertExpectations(t)
+	})
+
+	t.Run("does not retry a payment whose receipt failed", func(t *testing.T) {
+		loans := new(mockLoanRepository)
+		loans.On("RecordPayment", ctx, int64(3), 110000.0, at).Return(&loan.Loan{LoanID: 3, CustomerID: 7}, nil)
+		customers := new(mockCustomerRepository)
+		customers.On("FindByID", ctx, int64(7)).Return(johnDoe, nil)
+		notifications := new(mockNotificationService)
+		notifications.On("Notify", ctx, int64(7), notification.EventPaymentReceipt, mock.Anything).Return(nil, errors.New("sender down"))
+
+		handler := NewLoanEventHandler(loans, NewLoanNotifier(customers, notifications, "log"), discard)
+		err := handler.Apply(ctx, routingKeyLoanPaymentReceived, []byte(`{"loanId":3,"amount":110000,"timestamp":"2025-03-04T10:00:00Z"}`))
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("fails for retry when the loan is not replicated yet", func(t *testing.T) {
+		loans := new(mockLoanRepository)
+		loans.On("RecordPayment", ctx, int64(3), 110000.0, mock.Anything).Return(nil, loan.ErrNotFound)
+
+		err := NewLoanEventHandler(loans, nil, discard).Apply(ctx, routingKeyLoanPaymentReceived, []byte(`{"loanId":3,"amount":110000}`))
+
+		assert.ErrorIs(t, err, loan.ErrNotFound)
+	})
+
+	t.Run("marks a loan delinquent and sends the notice", func(t *testing.T) {
+		loans := new(mockLoanRepository)
+		loans.On("UpdateStatus", ctx, int64(3), loan.StatusDelinquent, at).Return(&loan.Loan{LoanID: 3, Status: loan.StatusDelinquent}, nil)
+		customers := new(mockCustomerRepository)
+		customers.On("FindByID", ctx, int64(7)).Return(johnDoe, nil)
+		notifications := new(mockNotificationService)
+		notifications.On("Notify", ctx, int64(7), notification.EventDelinquencyNotice, mock.MatchedBy(func(msg notification.Message) bool {
+			return msg.Channel == "log" && strings.Contains(msg.Body, "loan 3 is 21 days past due")
+		})).Return(&notification.Notification{}, nil)
+
+		handler := NewLoanEventHandler(loans, NewLoanNotifier(customers, notifications, "log"), discard)
+		err := handler.Apply(ctx, routingKeyLoanDelinquent, []byte(`{"loanId":3,"customerId":7,"daysPastDue":21,"bucket":"1-30","timestamp":"2025-03-04T10:00:00Z"}`))
+
+		require.NoError(t, err)
+		loans.AssertExpectations(t)
+		notifications.AssertExpectations(t)
+	})
+
+	t.Run("fails for retry when the delinquency notice cannot be sent", func(t *testing.T) {
+		loans := new(mockLoanRepository)
+		loans.On("UpdateStatus", ctx, int64(3), loan.StatusDelinquent, at).Return(&loan.Loan{LoanID: 3, Status: loan.StatusDelinquent}, nil)
+		customers := new(mockCustomerRepository)
+		customers.On("FindByID", ctx, int64(7)).Return(nil, customer.ErrNotFound)
+
+		handler := NewLoanEventHandler(loans, NewLoanNotifier(customers, new(mockNotificationService), "log"), discard)
+		err := handler.Apply(ctx, routingKeyLoanDelinquent, []byte(`{"loanId":3,"customerId":7,"daysPastDue":21,"timestamp":"2025-03-04T10:00:00Z"}`))
+
+		assert.ErrorIs(t, err, customer.ErrNotFound)
+	})
+
+	t.Run("sends the paid off notice", func(t *testing.T) {
+		loans := new(mockLoanRepository)
+		loans.On("UpdateStatus", ctx, int64(3), loan.StatusPaidOff, at).Return(&loan.Loan{LoanID: 3, CustomerID: 7, Status: loan.StatusPaidOff}, nil)
+		customers := new(mockCustomerRepository)
+		customers.On("FindByID", ctx, int64(7)).Return(johnDoe, nil)
+		notifications := new(mockNotificationService)
+		notifications.On("Notify", ctx, int64(7), notification.EventLoanPaidOff, mock.MatchedBy(func(msg notification.Message) bool {
+			return strings.Contains(msg.Body, "loan 3 is fully paid")
+		})).Return(&notification.Notification{}, nil)
+
+		handler := NewLoanEventHandler(loans, NewLoanNotifier(customers, notifications, "log"), discard)
+		err := handler.Apply(ctx, routingKeyLoanPaidOff, []byte(`{"loanId":3,"customerId":7,"timestamp":"2025-03-04T10:00:00Z"}`))
+
+		require.NoError(t, err)
+		notifications.AssertExpectations(t)
+	})
+
+	t.Run("emails the payoff certificate link", func(t *testing.T) {
+		loans := new(mockLoanRepository)
+		loans.On("UpdateStatus", ctx, int64(3), loan.StatusPaidOff, at).Return(&loan.Loan{LoanID: 3, CustomerID: 7, Status: loan.StatusPaidOff}, nil)
+		customers := new(mockCustomerRepository)
+		customers.On("FindByID", ctx, int64(7)).Return(johnDoe, nil)
+		notifications := new(mockNotificationService)
+		notifications.On("Notify", ctx, int64(7), notification.EventLoanPaidOff, mock.MatchedBy(func(msg notification.Message) bool {
+			return msg.Channel == "email" && strings.HasSuffix(msg.Body, "Download your payoff certificate at https://files.example/cert.pdf")
+		})).Return(&notification.Notification{}, nil)
+		notices := NewLoanNotifier(customers, notifications, "log")
+		notices.UseChannels([]string{"log", "email"})
+
+		handler := NewLoanEventHandler(loans, notices, discard)
+		err := handler.Apply(ctx, routingKeyLoanPaidOff, []byte(`{"loanId":3,"customerId":7,"documentId":12,"documentUrl":"https://files.example/cert.pdf","timestamp":"2025-03-04T10:00:00Z"}`))
+
+		require.NoError(t, err)
+		notifications.AssertExpectations(t)
+	})
+
+	t.Run("skips the paid off notice when a newer status is stored", func(t *testing.T) {
+		loans := new(mockLoanRepository)
+		loans.On("UpdateStatus", ctx, int64(3), loan.StatusPaidOff, at).Return(&loan.Loan{LoanID: 3, CustomerID: 7, Status: loan.StatusDelinquent}, nil)
+		notifications := new(mockNotificationService)
+
+		handler := NewLoanEventHandler(loans, NewLoanNotifier(new(mockCustomerRepository), notifications, "log"), discard)
+		err := handler.Apply(ctx, routingKeyLoanPaidOff, []byte(`{"loanId":3,"customerId":7,"timestamp":"2025-03-04T10:00:00Z"}`))
+
+		require.NoError(t, err)
+		notifications.AssertNotCalled(t, "Notify", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("rejects malformed events", func(t *testing.T) {
+		err := NewLoanEventHandler(new(mockLoanRepository), nil, discard).Apply(ctx, routingKeyLoanCreated, []byte(`{"loanId":"three"}`))
+
+		assert.ErrorIs(t, err, errMalformedEvent)
+	})
+}
+
+func TestCustomerEventHandlerRoutesLoanEvents(t *testing.T) {
+	ctx := context.Background()
+	discard := slog.New(slog.NewTextHandler(io.Discard, nil))
+	body := []byte(`{"eventId":"evt-9","loanId":3,"customerId":7}`)
+
+	t.Run("applies and records loan events", func(t *testing.T) {
+		loans := new(mockLoanRepository)
+		loans.On("UpdateStatus", ctx, int64(3), loan.StatusDelinquent, mock.Anything).Return(&loan.Loan{LoanID: 3}, nil)
+		processed := new(mockProcessedRepository)
+		processed.On("IsProcessed", ctx, "evt-9").Return(false, nil)
+		processed.On("MarkProcessed", ctx, "evt-9", routingKeyLoanDelinquent).Return(nil)
+
+		handler := NewCustomerEventHandler(nil, processed, nil, retry.Policy{}, discard)
+		handler.HandleLoanEvents(NewLoanEventHandler(loans, nil, discard))
+		err := handler.Process(ctx, routingKeyLoanDelinquent, body)
+
+		require.NoError(t, err)
+		loans.AssertExpectations(t)
+		processed.AssertExpectations(t)
+	})
+
+	t.Run("treats loan events as unknown without a loan handler", func(t *testing.T) {
+		err := NewCustomerEventHandler(nil, nil, nil, retry.Policy{}, discard).Process(ctx, routingKeyLoanDelinquent, body)
+
+		assert.ErrorIs(t, err, errUnknownRoutingKey)
+	})
+}
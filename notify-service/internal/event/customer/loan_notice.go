@@ -0,0 +1,116 @@
+package event
+
+import (
+	"context"
+	"fmt"
+	"notify-service/internal/domain/customer"
+	"notify-service/internal/domain/loan"
+	"notify-service/internal/domain/notification"
+	"notify-service/internal/i18n"
+)
+
+// certificateChannel is the channel a paid-off notice with a certificate
+// link prefers.
+const certificateChannel = "email"
+
+// LoanNotifier sends the messages a customer receives about one of their
+// loans: a confirmation when it is created, a receipt for every payment,
+// reminders and delinquency notices while it is past due and a final notice
+// once it is paid off.
+type LoanNotifier struct {
+	customers     customer.CustomerRepository
+	prefs         customer.PreferencesRepository
+	catalog       *i18n.Catalog
+	notifications notification.Service
+	channel       string
+	channels      map[string]bool
+}
+
+func NewLoanNotifier(customers customer.CustomerRepository, notifications notification.Service, channel string) *LoanNotifier {
+	return &LoanNotifier{
+		customers:     customers,
+		catalog:       i18n.BuiltIn(),
+		notifications: notifications,
+		channel:       channel,
+	}
+}
+
+// UsePreferences writes the messages in the customer's locale.
+func (n *LoanNotifier) UsePreferences(prefs customer.PreferencesRepository) {
+	n.prefs = prefs
+}
+
+// UseCatalog replaces the built-in message catalogs.
+func (n *LoanNotifier) UseCatalog(catalog *i18n.Catalog) {
+	n.catalog = catalog
+}
+
+// UseChannels lists the channels that have a sender. A reminder goes out on
+// the channel its ladder step names when it is one of them and on the
+// default channel otherwise, so a step is never lost to a missing provider.
+func (n *LoanNotifier) UseChannels(channels []string) {
+	n.channels = make(map[string]bool, len(channels))
+	for _, c := range channels {
+		n.channels[c] = true
+	}
+}
+
+func (n *LoanNotifier) LoanCreated(ctx context.Context, l *loan.Loan) error {
+	return n.send(ctx, l.CustomerID, l.LoanID, n.channel, notification.EventLoanConfirmation, i18n.Data{Principal: l.PrincipalAmount, TermWeeks: l.TermWeeks})
+}
+
+// PaymentReceived confirms amount; l already includes it.
+func (n *LoanNotifier) PaymentReceived(ctx context.Context, l *loan.Loan, amount float64) error {
+	return n.send(ctx, l.CustomerID, l.LoanID, n.channel, notification.EventPaymentReceipt, i18n.Data{Amount: amount, AmountPaid: l.AmountPaid})
+}
+
+// LoanPaidOff sends the final notice. With documentURL, the link to the
+// payoff certificate, the notice carries it and goes out by email when there
+// is an email sender, since a link is of little use in a text message.
+func (n *LoanNotifier) LoanPaidOff(ctx context.Context, l *loan.Loan, documentURL string) error {
+	channel := n.channel
+	if documentURL != "" && n.channels[certificateChannel] {
+		channel = certificateChannel
+	}
+	return n.send(ctx, l.CustomerID, l.LoanID, channel, notification.EventLoanPaidOff, i18n.Data{DocumentURL: documentURL})
+}
+
+// PaymentReminder tells the customer the loan is event.DaysPastDue days past
+// due. The loan read model is not needed, so a reminder also reaches loans
+// created before notify-service consumed loan events.
+func (n *LoanNotifier) PaymentReminder(ctx context.Context, event LoanReminderDueEvent) error {
+	channel := n.channel
+	if n.channels[event.Channel] {
+		channel = event.Channel
+	}
+	return n.send(ctx, event.CustomerID, event.LoanID, channel, notification.EventPaymentReminder, i18n.Data{DaysPastDue: event.DaysPastDue})
+}
+
+// DelinquencyNotice goes out on the default channel, like the other notices
+// that are not part of the reminder ladder.
+func (n *LoanNotifier) DelinquencyNotice(ctx context.Context, event LoanDelinquentEvent) error {
+	return n.send(ctx, event.CustomerID, event.LoanID, n.channel, notification.EventDelinquencyNotice, i18n.Data{DaysPastDue: event.DaysPastDue})
+}
+
+// send looks the customer up to address the message. A customer that has
+// not been replicated yet is reported as an error so the event is retried.
+func (n *LoanNotifier) send(ctx context.Context, customerID, loanID int64, channel, event string, data i18n.Data) error {
+	cust, err := n.customers.FindByID(ctx, customerID)
+	if err != nil {
+		return fmt.Errorf("failed to load customer %d for %s: %w", customerID, event, err)
+	}
+
+	data.Name, data.LoanID = cust.Name, loanID
+	subject, body, err := render(ctx, n.catalog, n.prefs, cust.CustomerID, event, data)
+	if err != nil {
+		return err
+	}
+
+	_, err = n.notifications.Notify(ctx, cust.CustomerID, event, notification.Message{
+		Channel:   channel,
+		Recipient: cust.Address,
+		Subject:   subject,
+		Body:      body,
+	})
+	return err
+}
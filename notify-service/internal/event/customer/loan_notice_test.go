@@ -0,0 +1,112 @@
+package event
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"notify-service/internal/domain/customer"
+	"notify-service/internal/domain/notification"
+	"notify-service/internal/domain/retry"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockCustomerRepository struct {
+	mock.Mock
+}
+
+func (m *mockCustomerRepository) Upsert(ctx context.Context, cust *customer.Customer) error {
+	return m.Called(ctx, cust).Error(0)
+}
+
+func (m *mockCustomerRepository) FindByID(ctx context.Context, customerID int64) (*customer.Customer, error) {
+	args := m.Called(ctx, customerID)
+	cust, _ := args.Get(0).(*customer.Customer)
+	return cust, args.Error(1)
+}
+
+type mockNotificationService struct {
+	mock.Mock
+}
+
+func (m *mockNotificationService) Notify(ctx context.Context, customerID int64, event string, msg notification.Message) (*notification.Notification, error) {
+	args := m.Called(ctx, customerID, event, msg)
+	n, _ := args.Get(0).(*notification.Notification)
+	return n, args.Error(1)
+}
+
+func (m *mockNotificationService) DispatchDeferred(ctx context.Context, lease time.Duration, limit int) (int, error) {
+	args := m.Called(ctx, lease, limit)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *mockNotificationService) ListByCustomer(ctx context.Context, customerID int64, limit int) ([]notification.Notification, error) {
+	args := m.Called(ctx, customerID, limit)
+	notifications, _ := args.Get(0).([]notification.Notification)
+	return notifications, args.Error(1)
+}
+
+func TestPaymentReminder(t *testing.T) {
+	ctx := context.Background()
+	discard := slog.New(slog.NewTextHandler(io.Discard, nil))
+	johnDoe := &customer.Customer{CustomerID: 7, Name: "John Doe", Address: "123 Main St"}
+	reminder := []byte(`{"eventId":"e-1","loanId":3,"customerId":7,"daysPastDue":8,"step":7,"channel":"email"}`)
+
+	t.Run("sends the reminder on the step's channel", func(t *testing.T) {
+		customers := new(mockCustomerRepository)
+		customers.On("FindByID", ctx, int64(7)).Return(johnDoe, nil)
+		notifications := new(mockNotificationService)
+		notifications.On("Notify", ctx, int64(7), notification.EventPaymentReminder, mock.MatchedBy(func(msg notification.Message) bool {
+			return msg.Channel == "email" && msg.Recipient == "123 Main St" && strings.Contains(msg.Body, "loan 3 is 8 days past due")
+		})).Return(&notification.Notification{}, nil)
+		notices := NewLoanNotifier(customers, notifications, "log")
+		notices.UseChannels([]string{"log", "email"})
+
+		handler := NewCustomerEventHandler(nil, nil, nil, retry.Policy{}, discard)
+		handler.HandleLoanEvents(NewLoanEventHandler(new(mockLoanRepository), notices, discard))
+		err := handler.Process(ctx, routingKeyLoanReminderDue, reminder)
+
+		require.NoError(t, err)
+		notifications.AssertExpectations(t)
+	})
+
+	t.Run("falls back to the default channel", func(t *testing.T) {
+		customers := new(mockCustomerRepository)
+		customers.On("FindByID", ctx, int64(7)).Return(johnDoe, nil)
+		notifications := new(mockNotificationService)
+		notifications.On("Notify", ctx, int64(7), notification.EventPaymentReminder, mock.MatchedBy(func(msg notification.Message) bool {
+			return msg.Channel == "log"
+		})).Return(&notification.Notification{}, nil)
+		notices := NewLoanNotifier(customers, notifications, "log")
+		notices.UseChannels([]string{"log", "sms"})
+
+		require.NoError(t, notices.PaymentReminder(ctx, LoanReminderDueEvent{LoanID: 3, CustomerID: 7, DaysPastDue: 8, Step: 7, Channel: "email"}))
+		notifications.AssertExpectations(t)
+	})
+
+	t.Run("fails for retry when customer is not replicated yet", func(t *testing.T) {
+		customers := new(mockCustomerRepository)
+		customers.On("FindByID", ctx, int64(7)).Return(nil, customer.ErrNotFound)
+
+		handler := NewCustomerEventHandler(nil, nil, nil, retry.Policy{}, discard)
+		handler.HandleLoanEvents(NewLoanEventHandler(new(mockLoanRepository), NewLoanNotifier(customers, new(mockNotificationService), "log"), discard))
+		err := handler.Process(ctx, routingKeyLoanReminderDue, reminder)
+
+		assert.ErrorIs(t, err, customer.ErrNotFound)
+		assert.NotErrorIs(t, err, errMalformedEvent)
+	})
+}
+
+func TestProcessDelinquencyChanged(t *testing.T) {
+	discard := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	err := NewCustomerEventHandler(nil, nil, nil, retry.Policy{}, discard).
+		Process(context.Background(), routingKeyDelinquencyChanged, []byte(`{"customerId":7,"loanId":3,"newStatus":true}`))
+
+	assert.NoError(t, err, "delinquency changes are acknowledged without a notice")
+}
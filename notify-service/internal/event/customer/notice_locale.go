@@ -0,0 +1,23 @@
+package event
+
+import (
+	"context"
+	"fmt"
+	"notify-service/internal/domain/customer"
+	"notify-service/internal/i18n"
+)
+
+// render looks up the customer's locale in prefs and renders the catalog
+// message for event in it. Without prefs every message uses the default
+// locale.
+func render(ctx context.Context, catalog *i18n.Catalog, prefs customer.PreferencesRepository, customerID int64, event string, data i18n.Data) (subject, body string, err error) {
+	locale := ""
+	if prefs != nil {
+		p, err := prefs.FindPreferences(ctx, customerID)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to load preferences of customer %d: %w", customerID, err)
+		}
+		locale = p.Language
+	}
+	return catalog.Render(locale, event, data)
+}
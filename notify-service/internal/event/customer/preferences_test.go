@@ -0,0 +1,131 @@
+package event
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"notify-service/internal/domain/customer"
+	"notify-service/internal/domain/loan"
+	"notify-service/internal/domain/notification"
+	"notify-service/internal/domain/retry"
+	"notify-service/internal/i18n"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockPreferencesRepository struct {
+	mock.Mock
+}
+
+func (m *mockPreferencesRepository) UpsertPreferences(ctx context.Context, p *customer.Preferences) error {
+	return m.Called(ctx, p).Error(0)
+}
+
+func (m *mockPreferencesRepository) FindPreferences(ctx context.Context, customerID int64) (*customer.Preferences, error) {
+	args := m.Called(ctx, customerID)
+	p, _ := args.Get(0).(*customer.Preferences)
+	return p, args.Error(1)
+}
+
+func TestProcessPreferencesChanged(t *testing.T) {
+	ctx := context.Background()
+	discard := slog.New(slog.NewTextHandler(io.Discard, nil))
+	body := []byte(`{"customerId":7,"preferredChannel":"email","language":"id","marketingOptOut":true,"transactionalOptOut":false,"updatedAt":"2025-03-01T08:00:00Z"}`)
+
+	t.Run("stores the preferences", func(t *testing.T) {
+		prefs := new(mockPreferencesRepository)
+		prefs.On("UpsertPreferences", ctx, &customer.Preferences{
+			CustomerID:       7,
+			PreferredChannel: "email",
+			Language:         "id",
+			MarketingOptOut:  true,
+			UpdatedAt:        time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC),
+		}).Return(nil)
+
+		handler := NewCustomerEventHandler(nil, nil, nil, retry.Policy{}, discard)
+		handler.HandlePreferences(prefs)
+
+		require.NoError(t, handler.Process(ctx, routingKeyPreferencesChanged, body))
+		prefs.AssertExpectations(t)
+	})
+
+	t.Run("rejected as unknown without a store", func(t *testing.T) {
+		err := NewCustomerEventHandler(nil, nil, nil, retry.Policy{}, discard).Process(ctx, routingKeyPreferencesChanged, body)
+
+		assert.ErrorIs(t, err, errUnknownRoutingKey)
+	})
+}
+
+func TestNoticesUsePreferredLanguage(t *testing.T) {
+	ctx := context.Background()
+	customers := new(mockCustomerRepository)
+	customers.On("FindByID", ctx, int64(7)).Return(&customer.Customer{CustomerID: 7, Name: "Budi", Address: "Jl. Merdeka 1"}, nil)
+	customers.On("FindByID", ctx, int64(8)).Return(&customer.Customer{CustomerID: 8, Name: "John", Address: "1 Main St"}, nil)
+	prefs := new(mockPreferencesRepository)
+	prefs.On("FindPreferences", ctx, int64(7)).Return(&customer.Preferences{CustomerID: 7, Language: "id-ID"}, nil)
+	prefs.On("FindPreferences", ctx, int64(8)).Return(&customer.Preferences{CustomerID: 8, Language: "fr"}, nil)
+
+	t.Run("payment reminder in Indonesian", func(t *testing.T) {
+		notifications := new(mockNotificationService)
+		notifications.On("Notify", ctx, int64(7), notification.EventPaymentReminder, mock.MatchedBy(func(msg notification.Message) bool {
+			return msg.Subject == "Pengingat pembayaran" && strings.HasPrefix(msg.Body, "Yth. Budi, pembayaran pinjaman 3 telah terlambat 14 hari.")
+		})).Return(&notification.Notification{}, nil)
+		notices := NewLoanNotifier(customers, notifications, "log")
+		notices.UsePreferences(prefs)
+
+		require.NoError(t, notices.PaymentReminder(ctx, LoanReminderDueEvent{LoanID: 3, CustomerID: 7, DaysPastDue: 14, Step: 14, Channel: "sms"}))
+		notifications.AssertExpectations(t)
+	})
+
+	t.Run("unsupported language falls back to English", func(t *testing.T) {
+		notifications := new(mockNotificationService)
+		notifications.On("Notify", ctx, int64(8), notification.EventLoanPaidOff, mock.MatchedBy(func(msg notification.Message) bool {
+			return msg.Subject == "Loan paid off" && msg.Body == "Dear John, loan 5 is fully paid. Thank you for your payments."
+		})).Return(&notification.Notification{}, nil)
+		loans := NewLoanNotifier(customers, notifications, "log")
+		loans.UsePreferences(prefs)
+
+		require.NoError(t, loans.LoanPaidOff(ctx, &loan.Loan{LoanID: 5, CustomerID: 8}, ""))
+		notifications.AssertExpectations(t)
+	})
+
+	t.Run("payment receipt in Indonesian", func(t *testing.T) {
+		notifications := new(mockNotificationService)
+		notifications.On("Notify", ctx, int64(7), notification.EventPaymentReceipt, mock.MatchedBy(func(msg notification.Message) bool {
+			return msg.Subject == "Pembayaran diterima" && strings.Contains(msg.Body, "sebesar 110,00 untuk pinjaman 5")
+		})).Return(&notification.Notification{}, nil)
+		loans := NewLoanNotifier(customers, notifications, "log")
+		loans.UsePreferences(prefs)
+
+		require.NoError(t, loans.PaymentReceived(ctx, &loan.Loan{LoanID: 5, CustomerID: 7, AmountPaid: 220}, 110))
+		notifications.AssertExpectations(t)
+	})
+}
+
+func TestNoticesUseCatalog(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "en.json"),
+		[]byte(`{"loan_paid_off":{"subject":"All done","body":"{{.Name}}, loan {{.LoanID}} is closed."}}`), 0o644))
+	catalog, err := i18n.Load(dir)
+	require.NoError(t, err)
+
+	customers := new(mockCustomerRepository)
+	customers.On("FindByID", ctx, int64(8)).Return(&customer.Customer{CustomerID: 8, Name: "John", Address: "1 Main St"}, nil)
+	notifications := new(mockNotificationService)
+	notifications.On("Notify", ctx, int64(8), notification.EventLoanPaidOff, mock.MatchedBy(func(msg notification.Message) bool {
+		return msg.Subject == "All done" && msg.Body == "John, loan 5 is closed."
+	})).Return(&notification.Notification{}, nil)
+	loans := NewLoanNotifier(customers, notifications, "log")
+	loans.UseCatalog(catalog)
+
+	require.NoError(t, loans.LoanPaidOff(ctx, &loan.Loan{LoanID: 5, CustomerID: 8}, ""))
+	notifications.AssertExpectations(t)
+}
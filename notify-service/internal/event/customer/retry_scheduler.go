@@ -0,0 +1,139 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"notify-service/internal/domain/retry"
+	"notify-service/internal/infrastructure/monitoring"
+	"sync"
+	"time"
+)
+
+// ProcessFunc applies one message; CustomerEventHandler.Process satisfies it.
+type ProcessFunc func(ctx context.Context, routingKey string, body []byte) error
+
+// RetrySchedulerConfig tunes the polling loop. Lease bounds how long a
+// claimed retry stays hidden from other instances while it runs.
+type RetrySchedulerConfig struct {
+	Interval  time.Duration
+	BatchSize int
+	Lease     time.Duration
+	Policy    retry.Policy
+}
+
+// RetryScheduler polls the retry store and runs due retries through the same
+// processing as live deliveries.
+type RetryScheduler struct {
+	process    ProcessFunc
+	retries    retry.Repository
+	cfg        RetrySchedulerConfig
+	logger     *slog.Logger
+	now        func() time.Time
+	wg         sync.WaitGroup
+	cancelFunc context.CancelFunc
+}
+
+func NewRetryScheduler(process ProcessFunc, retries retry.Repository, cfg RetrySchedulerConfig, logger *slog.Logger) *RetryScheduler {
+	if process == nil || retries == nil {
+		panic("RetryScheduler dependencies cannot be nil")
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 15 * time.Second
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 50
+	}
+	if cfg.Lease <= 0 {
+		cfg.Lease = 5 * time.Minute
+	}
+	return &RetryScheduler{
+		process: process,
+		retries: retries,
+		cfg:     cfg,
+		logger:  logger.With("component", "RetryScheduler"),
+		now:     time.Now,
+	}
+}
+
+func (s *RetryScheduler) Start(ctx context.Context) {
+	loopCtx, cancel := context.WithCancel(ctx)
+	s.cancelFunc = cancel
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.logger.Info("Retry scheduler started.", "interval", s.cfg.Interval)
+		ticker := time.NewTicker(s.cfg.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-loopCtx.Done():
+				s.logger.Info("Retry scheduler context cancelled. Exiting loop.")
+				return
+			case <-ticker.C:
+				if _, err := s.RunOnce(loopCtx); err != nil {
+					s.logger.Error("Retry run failed", slog.Any("error", err))
+				}
+			}
+		}
+	}()
+}
+
+func (s *RetryScheduler) Stop() {
+	if s.cancelFunc == nil {
+		return
+	}
+	s.logger.Info("Stopping retry scheduler...")
+	s.cancelFunc()
+	s.wg.Wait()
+	s.logger.Info("Retry scheduler stopped.")
+}
+
+// RunOnce claims one batch of due retries and runs them, returning how many
+// succeeded.
+func (s *RetryScheduler) RunOnce(ctx context.Context) (int, error) {
+	now := s.now()
+	due, err := s.retries.ClaimDue(ctx, now, now.Add(s.cfg.Lease), s.cfg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	succeeded := 0
+	for _, rt := range due {
+		logCtx := s.logger.With(slog.Int64("retryID", rt.ID), slog.String("routingKey", rt.RoutingKey), slog.Int("attempts", rt.Attempts))
+
+		processErr := s.process(ctx, rt.RoutingKey, rt.Body)
+		if processErr == nil {
+			if err := s.retries.Delete(ctx, rt.ID); err != nil {
+				logCtx.ErrorContext(ctx, "Retry succeeded but could not be removed", slog.Any("error", err))
+			}
+			monitoring.RecordRetry("succeeded")
+			logCtx.InfoContext(ctx, "Retry succeeded")
+			succeeded++
+			continue
+		}
+
+		attempts := rt.Attempts + 1
+		var nextAttemptAt *time.Time
+		if next, ok := s.cfg.Policy.Next(s.now(), attempts); ok && !isPermanent(processErr) {
+			nextAttemptAt = &next
+		}
+		if err := s.retries.Reschedule(ctx, rt.ID, attempts, processErr.Error(), nextAttemptAt); err != nil {
+			logCtx.ErrorContext(ctx, "Failed to reschedule retry", slog.Any("error", err))
+			continue
+		}
+		if nextAttemptAt == nil {
+			monitoring.RecordRetry("parked")
+			logCtx.ErrorContext(ctx, "Giving up on retry, parked for inspection", slog.Any("error", processErr))
+		} else {
+			monitoring.RecordRetry("rescheduled")
+			logCtx.WarnContext(ctx, "Retry failed, rescheduled", slog.Any("error", processErr), slog.Time("nextAttemptAt", *nextAttemptAt))
+		}
+	}
+	return succeeded, nil
+}
+
+func isPermanent(err error) bool {
+	return errors.Is(err, errUnknownRoutingKey) || errors.Is(err, errMalformedEvent)
+}
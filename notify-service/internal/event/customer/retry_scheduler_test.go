@@ -0,0 +1,113 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"notify-service/internal/domain/retry"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockRetryRepository struct {
+	mock.Mock
+}
+
+func (m *mockRetryRepository) Enqueue(ctx context.Context, routingKey string, body []byte, lastError string, nextAttemptAt time.Time) error {
+	return m.Called(ctx, routingKey, body, lastError, nextAttemptAt).Error(0)
+}
+
+func (m *mockRetryRepository) ClaimDue(ctx context.Context, now, leaseUntil time.Time, limit int) ([]retry.Retry, error) {
+	args := m.Called(ctx, now, leaseUntil, limit)
+	retries, _ := args.Get(0).([]retry.Retry)
+	return retries, args.Error(1)
+}
+
+func (m *mockRetryRepository) Reschedule(ctx context.Context, id int64, attempts int, lastError string, nextAttemptAt *time.Time) error {
+	return m.Called(ctx, id, attempts, lastError, nextAttemptAt).Error(0)
+}
+
+func (m *mockRetryRepository) Delete(ctx context.Context, id int64) error {
+	return m.Called(ctx, id).Error(0)
+}
+
+func newTestScheduler(process ProcessFunc, repo retry.Repository, now time.Time) *RetryScheduler {
+	s := NewRetryScheduler(process, repo, RetrySchedulerConfig{
+		BatchSize: 10,
+		Lease:     time.Minute,
+		Policy:    retry.Policy{MaxAttempts: 3, BaseDelay: 30 * time.Second, MaxDelay: time.Hour},
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	s.now = func() time.Time { return now }
+	return s
+}
+
+func TestRetrySchedulerRunOnce(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 4, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("deletes retries that succeed", func(t *testing.T) {
+		repo := new(mockRetryRepository)
+		repo.On("ClaimDue", ctx, now, now.Add(time.Minute), 10).
+			Return([]retry.Retry{{ID: 1, RoutingKey: routingKeyCustomerCreated, Attempts: 1}}, nil)
+		repo.On("Delete", ctx, int64(1)).Return(nil)
+
+		succeeded, err := newTestScheduler(func(context.Context, string, []byte) error { return nil }, repo, now).RunOnce(ctx)
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, succeeded)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("backs off retries that fail again", func(t *testing.T) {
+		repo := new(mockRetryRepository)
+		repo.On("ClaimDue", ctx, now, now.Add(time.Minute), 10).
+			Return([]retry.Retry{{ID: 1, RoutingKey: routingKeyCustomerCreated, Attempts: 1}}, nil)
+		next := now.Add(time.Minute)
+		repo.On("Reschedule", ctx, int64(1), 2, "db down", &next).Return(nil)
+
+		succeeded, err := newTestScheduler(func(context.Context, string, []byte) error { return errors.New("db down") }, repo, now).RunOnce(ctx)
+
+		require.NoError(t, err)
+		assert.Zero(t, succeeded)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("parks retries out of attempts", func(t *testing.T) {
+		repo := new(mockRetryRepository)
+		repo.On("ClaimDue", ctx, now, now.Add(time.Minute), 10).
+			Return([]retry.Retry{{ID: 1, RoutingKey: routingKeyCustomerCreated, Attempts: 2}}, nil)
+		repo.On("Reschedule", ctx, int64(1), 3, "db down", (*time.Time)(nil)).Return(nil)
+
+		_, err := newTestScheduler(func(context.Context, string, []byte) error { return errors.New("db down") }, repo, now).RunOnce(ctx)
+
+		require.NoError(t, err)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("parks malformed messages at once", func(t *testing.T) {
+		repo := new(mockRetryRepository)
+		repo.On("ClaimDue", ctx, now, now.Add(time.Minute), 10).
+			Return([]retry.Retry{{ID: 1, RoutingKey: "customer.deleted", Attempts: 1}}, nil)
+		repo.On("Reschedule", ctx, int64(1), 2, mock.Anything, (*time.Time)(nil)).Return(nil)
+
+		handler := NewCustomerEventHandler(nil, nil, repo, retry.Policy{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+		_, err := newTestScheduler(handler.Process, repo, now).RunOnce(ctx)
+
+		require.NoError(t, err)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("returns claim errors", func(t *testing.T) {
+		repo := new(mockRetryRepository)
+		repo.On("ClaimDue", ctx, now, now.Add(time.Minute), 10).Return(nil, errors.New("db down"))
+
+		_, err := newTestScheduler(func(context.Context, string, []byte) error { return nil }, repo, now).RunOnce(ctx)
+
+		assert.Error(t, err)
+	})
+}
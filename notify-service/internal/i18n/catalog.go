@@ -0,0 +1,204 @@
+// Package i18n renders customer messages from per-locale catalogs. The
+// English and Indonesian catalogs are built in; a directory of JSON files can
+// override their messages or add locales.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"text/template"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// DefaultLocale is used for customers without a locale or with one that has
+// no catalog, and for messages missing from a locale's catalog.
+const DefaultLocale = "en"
+
+//go:embed locales/*.json
+var builtIn embed.FS
+
+// Data is what the message templates can refer to. LoanID is zero when a
+// message is not about one loan, Code is empty unless it carries a
+// verification code and DocumentURL is empty unless it links a document.
+type Data struct {
+	Name             string
+	LoanID           int64
+	Principal        float64
+	TermWeeks        int
+	Amount           float64
+	AmountPaid       float64
+	DaysPastDue      int
+	Code             string
+	CodeValidMinutes int
+	DocumentURL      string
+}
+
+type entry struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+type compiled struct {
+	subject *template.Template
+	body    *template.Template
+}
+
+// Catalog holds the messages of every locale, keyed by locale and then by
+// notification event.
+type Catalog struct {
+	locales map[string]map[string]compiled
+}
+
+// Load reads the built-in catalogs and then every <locale>.json in dir, whose
+// messages replace the built-in ones of the same locale and key. An empty dir
+// loads only the built-in catalogs.
+func Load(dir string) (*Catalog, error) {
+	c := &Catalog{locales: map[string]map[string]compiled{}}
+	if err := c.loadFS(builtIn, "locales"); err != nil {
+		return nil, err
+	}
+	if dir != "" {
+		if err := c.loadFS(os.DirFS(dir), "."); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+var builtInCatalog *Catalog
+
+// BuiltIn returns the catalogs compiled into the binary.
+func BuiltIn() *Catalog {
+	return builtInCatalog
+}
+
+func init() {
+	c, err := Load("")
+	if err != nil {
+		panic(fmt.Sprintf("built-in message catalogs are invalid: %v", err))
+	}
+	builtInCatalog = c
+}
+
+func (c *Catalog) loadFS(fsys fs.FS, dir string) error {
+	files, err := fs.Glob(fsys, path.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		locale := strings.TrimSuffix(path.Base(file), ".json")
+		if _, err := language.Parse(locale); err != nil {
+			return fmt.Errorf("catalog %s is not named after a locale: %w", file, err)
+		}
+		raw, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return fmt.Errorf("failed to read catalog %s: %w", file, err)
+		}
+		var entries map[string]entry
+		if err := json.Unmarshal(raw, &entries); err != nil {
+			return fmt.Errorf("failed to parse catalog %s: %w", file, err)
+		}
+		locale = strings.ToLower(locale)
+		if c.locales[locale] == nil {
+			c.locales[locale] = map[string]compiled{}
+		}
+		for key, e := range entries {
+			m, err := compile(locale, key, e)
+			if err != nil {
+				return fmt.Errorf("catalog %s: %w", file, err)
+			}
+			c.locales[locale][key] = m
+		}
+	}
+	return nil
+}
+
+func compile(locale, key string, e entry) (compiled, error) {
+	funcs := template.FuncMap{"amount": amountFormatter(locale)}
+	subject, err := template.New(key + ".subject").Funcs(funcs).Parse(e.Subject)
+	if err != nil {
+		return compiled{}, err
+	}
+	body, err := template.New(key + ".body").Funcs(funcs).Parse(e.Body)
+	if err != nil {
+		return compiled{}, err
+	}
+	return compiled{subject: subject, body: body}, nil
+}
+
+// validate renders every message once so that a template referring to
+// something Data does not have fails at startup instead of on a send.
+func (c *Catalog) validate() error {
+	if _, ok := c.locales[DefaultLocale]; !ok {
+		return fmt.Errorf("no %s catalog", DefaultLocale)
+	}
+	for locale, messages := range c.locales {
+		for key, m := range messages {
+			if err := m.subject.Execute(discard{}, Data{}); err != nil {
+				return fmt.Errorf("%s %s: %w", locale, key, err)
+			}
+			if err := m.body.Execute(discard{}, Data{}); err != nil {
+				return fmt.Errorf("%s %s: %w", locale, key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Resolve returns the catalog locale used for a customer locale: the locale
+// itself, else its language without the region, else DefaultLocale. So
+// "id-ID" resolves to "id" and "fr" to "en".
+func (c *Catalog) Resolve(locale string) string {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	if _, ok := c.locales[locale]; ok {
+		return locale
+	}
+	primary, _, _ := strings.Cut(locale, "-")
+	if _, ok := c.locales[primary]; ok {
+		return primary
+	}
+	return DefaultLocale
+}
+
+// Render returns the subject and body of key in the customer's locale,
+// falling back to DefaultLocale when that locale lacks the message.
+func (c *Catalog) Render(locale, key string, data Data) (subject, body string, err error) {
+	m, ok := c.locales[c.Resolve(locale)][key]
+	if !ok {
+		m, ok = c.locales[DefaultLocale][key]
+	}
+	if !ok {
+		return "", "", fmt.Errorf("no message %q in the catalogs", key)
+	}
+	var s, b strings.Builder
+	if err := m.subject.Execute(&s, data); err != nil {
+		return "", "", fmt.Errorf("failed to render %s subject: %w", key, err)
+	}
+	if err := m.body.Execute(&b, data); err != nil {
+		return "", "", fmt.Errorf("failed to render %s body: %w", key, err)
+	}
+	return s.String(), b.String(), nil
+}
+
+// amountFormatter formats money with two decimals and the locale's
+// separators, 1,234.50 in English and 1.234,50 in Indonesian.
+func amountFormatter(locale string) func(float64) string {
+	printer := message.NewPrinter(language.Make(locale))
+	return func(v float64) string {
+		return printer.Sprintf("%.2f", v)
+	}
+}
+
+type discard struct{}
+
+func (discard) Write(p []byte) (int, error) { return len(p), nil }
@@ -0,0 +1,95 @@
+package i18n
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuiltInCatalogsHaveEveryMessage(t *testing.T) {
+	c := BuiltIn()
+	for locale, messages := range c.locales {
+		for key := range c.locales[DefaultLocale] {
+			assert.Contains(t, messages, key, "%s catalog misses %s", locale, key)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	c := BuiltIn()
+
+	subject, body, err := c.Render("id-ID", "payment_receipt", Data{Name: "Budi", LoanID: 5, Amount: 1250000, AmountPaid: 2500000.5})
+	require.NoError(t, err)
+	assert.Equal(t, "Pembayaran diterima", subject)
+	assert.Equal(t, "Yth. Budi, kami telah menerima pembayaran Anda sebesar 1.250.000,00 untuk pinjaman 5. Total yang telah Anda bayar 2.500.000,50.", body)
+
+	_, body, err = c.Render("", "payment_reminder", Data{Name: "John", LoanID: 3, DaysPastDue: 1})
+	require.NoError(t, err)
+	assert.Equal(t, "Dear John, loan 3 is 1 day past due. Please pay the outstanding installments to avoid further charges.", body)
+
+	subject, body, err = c.Render("id", "delinquency_notice", Data{Name: "Budi", LoanID: 3, DaysPastDue: 35})
+	require.NoError(t, err)
+	assert.Equal(t, "Akun Anda menunggak", subject)
+	assert.Equal(t, "Yth. Budi, pinjaman 3 telah terlambat 35 hari dan akun Anda kini tercatat menunggak. Mohon segera bayar angsuran yang tertunggak atau hubungi kami untuk mengatur pembayaran.", body)
+
+	_, body, err = c.Render("en-GB", "loan_confirmation", Data{Name: "John", LoanID: 3, Principal: 5000000, TermWeeks: 50})
+	require.NoError(t, err)
+	assert.Equal(t, "Dear John, loan 3 of 5,000,000.00 over 50 weeks is now active.", body)
+
+	_, body, err = c.Render("id", "loan_paid_off", Data{Name: "Budi", LoanID: 3, DocumentURL: "https://files.example/cert.pdf"})
+	require.NoError(t, err)
+	assert.Equal(t, "Yth. Budi, pinjaman 3 telah lunas. Terima kasih atas pembayaran Anda. Unduh surat keterangan lunas Anda di https://files.example/cert.pdf", body)
+
+	subject, body, err = c.Render("id", "contact_verification", Data{Code: "482913", CodeValidMinutes: 15})
+	require.NoError(t, err)
+	assert.Equal(t, "Kode verifikasi Anda", subject)
+	assert.Equal(t, "Kode verifikasi Anda adalah 482913. Kode ini berlaku selama 15 menit. Jangan berikan kode ini kepada siapa pun.", body)
+
+	_, _, err = c.Render("en", "spring_promotion", Data{})
+	assert.Error(t, err)
+}
+
+func TestResolve(t *testing.T) {
+	c := BuiltIn()
+	assert.Equal(t, "id", c.Resolve("id"))
+	assert.Equal(t, "id", c.Resolve("ID-id"))
+	assert.Equal(t, "en", c.Resolve("fr"))
+	assert.Equal(t, "en", c.Resolve(""))
+}
+
+func TestLoadOverrides(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+	}
+
+	t.Run("overrides a message and adds a locale", func(t *testing.T) {
+		write("id.json", `{"loan_paid_off":{"subject":"Lunas!","body":"Selamat {{.Name}}"}}`)
+		write("ms.json", `{"loan_paid_off":{"subject":"Pinjaman selesai","body":"Tahniah {{.Name}}"}}`)
+
+		c, err := Load(dir)
+		require.NoError(t, err)
+		subject, body, err := c.Render("id", "loan_paid_off", Data{Name: "Budi"})
+		require.NoError(t, err)
+		assert.Equal(t, "Lunas!", subject)
+		assert.Equal(t, "Selamat Budi", body)
+
+		_, body, err = c.Render("ms-MY", "loan_paid_off", Data{Name: "Aisyah"})
+		require.NoError(t, err)
+		assert.Equal(t, "Tahniah Aisyah", body)
+
+		subject, _, err = c.Render("ms", "payment_receipt", Data{})
+		require.NoError(t, err)
+		assert.Equal(t, "Payment received", subject, "a message missing from a locale falls back to English")
+	})
+
+	t.Run("rejects a template using unknown data", func(t *testing.T) {
+		write("id.json", `{"loan_paid_off":{"subject":"Lunas","body":"{{.Phone}}"}}`)
+
+		_, err := Load(dir)
+		assert.ErrorContains(t, err, "loan_paid_off")
+	})
+}
@@ -0,0 +1,82 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"notify-service/internal/domain/customer"
+	"notify-service/internal/infrastructure/monitoring"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var _ customer.ContactsRepository = (*CustomerRepository)(nil)
+
+const (
+	upsertContactsSQL = `
+		INSERT INTO customer_contacts (customer_id, contacts, updated_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (customer_id) DO UPDATE SET
+			contacts = EXCLUDED.contacts,
+			updated_at = EXCLUDED.updated_at
+		WHERE customer_contacts.updated_at < EXCLUDED.updated_at`
+	findContactsSQL = `SELECT customer_id, contacts, updated_at FROM customer_contacts WHERE customer_id = $1`
+)
+
+// contactJSON is how a contact is kept in the contacts column.
+type contactJSON struct {
+	ContactID int64  `json:"contactId"`
+	Type      string `json:"type"`
+	Value     string `json:"value"`
+	Verified  bool   `json:"verified"`
+	Primary   bool   `json:"primary"`
+}
+
+func (r *CustomerRepository) UpsertContacts(ctx context.Context, c *customer.Contacts) error {
+	rows := make([]contactJSON, 0, len(c.Contacts))
+	for _, contact := range c.Contacts {
+		rows = append(rows, contactJSON(contact))
+	}
+	raw, err := json.Marshal(rows)
+	if err != nil {
+		return fmt.Errorf("failed to encode contacts of customer %d: %w", c.CustomerID, err)
+	}
+
+	startTime := time.Now()
+	_, err = r.db.Exec(ctx, upsertContactsSQL, c.CustomerID, raw, c.UpdatedAt)
+	monitoring.RecordDBQuery("UpsertContacts", queryStatus(err), time.Since(startTime))
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to upsert customer contacts", slog.Int64("customerID", c.CustomerID), slog.Any("error", err))
+		return fmt.Errorf("failed to upsert contacts of customer %d: %w", c.CustomerID, err)
+	}
+	return nil
+}
+
+func (r *CustomerRepository) FindContacts(ctx context.Context, customerID int64) (*customer.Contacts, error) {
+	startTime := time.Now()
+	var (
+		c   customer.Contacts
+		raw []byte
+	)
+	err := r.db.QueryRow(ctx, findContactsSQL, customerID).Scan(&c.CustomerID, &raw, &c.UpdatedAt)
+	monitoring.RecordDBQuery("FindContacts", queryStatus(err), time.Since(startTime))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &customer.Contacts{CustomerID: customerID}, nil
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to find customer contacts", slog.Int64("customerID", customerID), slog.Any("error", err))
+		return nil, fmt.Errorf("failed to find contacts of customer %d: %w", customerID, err)
+	}
+
+	var rows []contactJSON
+	if err := json.Unmarshal(raw, &rows); err != nil {
+		return nil, fmt.Errorf("failed to decode contacts of customer %d: %w", customerID, err)
+	}
+	for _, row := range rows {
+		c.Contacts = append(c.Contacts, customer.Contact(row))
+	}
+	return &c, nil
+}
@@ -0,0 +1,75 @@
+package postgres
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"notify-service/internal/domain/customer"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCustomerRepositoryUpsertContacts(t *testing.T) {
+	ctx, repo, mockPool := setupCustomerRepo(t)
+	defer mockPool.Close()
+	contacts := &customer.Contacts{CustomerID: 7, UpdatedAt: time.Now(), Contacts: []customer.Contact{
+		{ContactID: 12, Type: customer.ContactPhone, Value: "+6281234567890", Verified: true, Primary: true},
+	}}
+	stored := []byte(`[{"contactId":12,"type":"phone","value":"+6281234567890","verified":true,"primary":true}]`)
+
+	t.Run("successful upsert", func(t *testing.T) {
+		mockPool.ExpectExec(regexp.QuoteMeta(upsertContactsSQL)).WithArgs(int64(7), stored, contacts.UpdatedAt).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+		assert.NoError(t, repo.UpsertContacts(ctx, contacts))
+	})
+
+	t.Run("no contacts are stored as an empty list", func(t *testing.T) {
+		mockPool.ExpectExec(regexp.QuoteMeta(upsertContactsSQL)).WithArgs(int64(8), []byte(`[]`), contacts.UpdatedAt).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+		assert.NoError(t, repo.UpsertContacts(ctx, &customer.Contacts{CustomerID: 8, UpdatedAt: contacts.UpdatedAt}))
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		mockPool.ExpectExec(regexp.QuoteMeta(upsertContactsSQL)).WithArgs(int64(7), stored, contacts.UpdatedAt).
+			WillReturnError(errors.New("connection reset"))
+
+		assert.Error(t, repo.UpsertContacts(ctx, contacts))
+	})
+
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestCustomerRepositoryFindContacts(t *testing.T) {
+	ctx, repo, mockPool := setupCustomerRepo(t)
+	defer mockPool.Close()
+
+	t.Run("stored contacts", func(t *testing.T) {
+		updatedAt := time.Date(2025, 3, 5, 9, 0, 0, 0, time.UTC)
+		mockPool.ExpectQuery(regexp.QuoteMeta(findContactsSQL)).WithArgs(int64(7)).
+			WillReturnRows(pgxmock.NewRows([]string{"customer_id", "contacts", "updated_at"}).
+				AddRow(int64(7), []byte(`[{"contactId":13,"type":"email","value":"ayu@example.com","verified":true,"primary":false}]`), updatedAt))
+
+		contacts, err := repo.FindContacts(ctx, 7)
+		require.NoError(t, err)
+		assert.Equal(t, &customer.Contacts{CustomerID: 7, UpdatedAt: updatedAt, Contacts: []customer.Contact{
+			{ContactID: 13, Type: customer.ContactEmail, Value: "ayu@example.com", Verified: true},
+		}}, contacts)
+	})
+
+	t.Run("never replicated", func(t *testing.T) {
+		mockPool.ExpectQuery(regexp.QuoteMeta(findContactsSQL)).WithArgs(int64(8)).WillReturnError(pgx.ErrNoRows)
+
+		contacts, err := repo.FindContacts(ctx, 8)
+		require.NoError(t, err)
+		assert.Equal(t, &customer.Contacts{CustomerID: 8}, contacts)
+	})
+
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
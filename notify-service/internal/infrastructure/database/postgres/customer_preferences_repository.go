@@ -0,0 +1,59 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"notify-service/internal/domain/customer"
+	"notify-service/internal/infrastructure/monitoring"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var _ customer.PreferencesRepository = (*CustomerRepository)(nil)
+
+const (
+	upsertPreferencesSQL = `
+		INSERT INTO customer_preferences (customer_id, preferred_channel, language, marketing_opt_out, transactional_opt_out, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (customer_id) DO UPDATE SET
+			preferred_channel = EXCLUDED.preferred_channel,
+			language = EXCLUDED.language,
+			marketing_opt_out = EXCLUDED.marketing_opt_out,
+			transactional_opt_out = EXCLUDED.transactional_opt_out,
+			updated_at = EXCLUDED.updated_at
+		WHERE customer_preferences.updated_at < EXCLUDED.updated_at`
+	findPreferencesSQL = `
+		SELECT customer_id, preferred_channel, language, marketing_opt_out, transactional_opt_out, updated_at
+		FROM customer_preferences WHERE customer_id = $1`
+)
+
+func (r *CustomerRepository) UpsertPreferences(ctx context.Context, p *customer.Preferences) error {
+	startTime := time.Now()
+	_, err := r.db.Exec(ctx, upsertPreferencesSQL,
+		p.CustomerID, p.PreferredChannel, p.Language, p.MarketingOptOut, p.TransactionalOptOut, p.UpdatedAt)
+	monitoring.RecordDBQuery("UpsertPreferences", queryStatus(err), time.Since(startTime))
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to upsert customer preferences", slog.Int64("customerID", p.CustomerID), slog.Any("error", err))
+		return fmt.Errorf("failed to upsert preferences of customer %d: %w", p.CustomerID, err)
+	}
+	return nil
+}
+
+func (r *CustomerRepository) FindPreferences(ctx context.Context, customerID int64) (*customer.Preferences, error) {
+	startTime := time.Now()
+	var p customer.Preferences
+	err := r.db.QueryRow(ctx, findPreferencesSQL, customerID).Scan(
+		&p.CustomerID, &p.PreferredChannel, &p.Language, &p.MarketingOptOut, &p.TransactionalOptOut, &p.UpdatedAt)
+	monitoring.RecordDBQuery("FindPreferences", queryStatus(err), time.Since(startTime))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &customer.Preferences{CustomerID: customerID}, nil
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to find customer preferences", slog.Int64("customerID", customerID), slog.Any("error", err))
+		return nil, fmt.Errorf("failed to find preferences of customer %d: %w", customerID, err)
+	}
+	return &p, nil
+}
@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"notify-service/internal/domain/customer"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCustomerRepositoryUpsertPreferences(t *testing.T) {
+	ctx, repo, mockPool := setupCustomerRepo(t)
+	defer mockPool.Close()
+	prefs := &customer.Preferences{CustomerID: 1, PreferredChannel: "email", Language: "id", MarketingOptOut: true, UpdatedAt: time.Now()}
+
+	t.Run("successful upsert", func(t *testing.T) {
+		mockPool.ExpectExec(regexp.QuoteMeta(upsertPreferencesSQL)).
+			WithArgs(int64(1), "email", "id", true, false, prefs.UpdatedAt).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+		assert.NoError(t, repo.UpsertPreferences(ctx, prefs))
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		mockPool.ExpectExec(regexp.QuoteMeta(upsertPreferencesSQL)).
+			WithArgs(int64(1), "email", "id", true, false, prefs.UpdatedAt).
+			WillReturnError(errors.New("connection reset"))
+
+		assert.Error(t, repo.UpsertPreferences(ctx, prefs))
+	})
+
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestCustomerRepositoryFindPreferences(t *testing.T) {
+	ctx, repo, mockPool := setupCustomerRepo(t)
+	defer mockPool.Close()
+	columns := []string{"customer_id", "preferred_channel", "language", "marketing_opt_out", "transactional_opt_out", "updated_at"}
+
+	t.Run("stored preferences", func(t *testing.T) {
+		updatedAt := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+		mockPool.ExpectQuery(regexp.QuoteMeta(findPreferencesSQL)).WithArgs(int64(1)).
+			WillReturnRows(pgxmock.NewRows(columns).AddRow(int64(1), "sms", "en", false, true, updatedAt))
+
+		prefs, err := repo.FindPreferences(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, &customer.Preferences{CustomerID: 1, PreferredChannel: "sms", Language: "en", TransactionalOptOut: true, UpdatedAt: updatedAt}, prefs)
+	})
+
+	t.Run("never set", func(t *testing.T) {
+		mockPool.ExpectQuery(regexp.QuoteMeta(findPreferencesSQL)).WithArgs(int64(2)).WillReturnError(pgx.ErrNoRows)
+
+		prefs, err := repo.FindPreferences(ctx, 2)
+		require.NoError(t, err)
+		assert.Equal(t, &customer.Preferences{CustomerID: 2}, prefs)
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		mockPool.ExpectQuery(regexp.QuoteMeta(findPreferencesSQL)).WithArgs(int64(3)).WillReturnError(errors.New("connection reset"))
+
+		_, err := repo.FindPreferences(ctx, 3)
+		assert.Error(t, err)
+	})
+
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
@@ -2,6 +2,7 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"notify-service/internal/domain/customer"
@@ -58,8 +59,8 @@ func (r *CustomerRepository) Upsert(ctx context.Context, cust *customer.Customer
 	status := "success"
 
 	upsertSQL := `
-		INSERT INTO customers (id, name, address, is_delinquent, active, loan_id, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO customers (id, name, address, is_delinquent, active, loan_id, created_at, updated_at, sequence)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 		ON CONFLICT (id) DO UPDATE SET
 			name = EXCLUDED.name,
 			address = EXCLUDED.address,
@@ -67,8 +68,11 @@ func (r *CustomerRepository) Upsert(ctx context.Context, cust *customer.Customer
 			active = EXCLUDED.active,
 			loan_id = EXCLUDED.loan_id,
 			-- created_at should not be updated on conflict
-			updated_at = EXCLUDED.updated_at
-		WHERE customers.updated_at < EXCLUDED.updated_at
+			updated_at = EXCLUDED.updated_at,
+			sequence = GREATEST(customers.sequence, EXCLUDED.sequence)
+		WHERE CASE WHEN customers.sequence > 0 AND EXCLUDED.sequence > 0
+			THEN customers.sequence < EXCLUDED.sequence
+			ELSE customers.updated_at < EXCLUDED.updated_at END
 		RETURNING (xmax = 0) AS is_insert;
 	`
 
@@ -82,20 +86,59 @@ func (r *CustomerRepository) Upsert(ctx context.Context, cust *customer.Customer
 		cust.LoanID,
 		cust.CreatedAt,
 		cust.UpdatedAt,
+		cust.Sequence,
 	).Scan(&isInsert)
+	stale := errors.Is(err, pgx.ErrNoRows)
+	if stale {
+		// The stored copy is newer, so the update changed no row.
+		err = nil
+	}
 
 	if err != nil {
 		status = "error"
 	}
 
 	monitoring.RecordDBQuery("Upsert", status, time.Since(startTime))
-	monitoring.RecordCostumer(isInsert)
 
 	if err != nil {
 		r.logger.ErrorContext(ctx, "Database upsert failed", slog.Any("error", err))
 		return fmt.Errorf("failed to upsert customer %d: %w", cust.CustomerID, err)
 	}
+	if stale {
+		r.logger.InfoContext(ctx, "Skipped customer copy older than the stored one",
+			slog.Int64("customerID", cust.CustomerID), slog.Int64("sequence", cust.Sequence))
+		return nil
+	}
+	monitoring.RecordCostumer(isInsert)
 
 	r.logger.InfoContext(ctx, "Customer upsert successful")
 	return nil
 }
+
+func (r *CustomerRepository) FindByID(ctx context.Context, customerID int64) (*customer.Customer, error) {
+	startTime := time.Now()
+	findSQL := `
+		SELECT id, name, address, is_delinquent, active, loan_id, created_at, updated_at
+		FROM customers WHERE id = $1`
+
+	var cust customer.Customer
+	err := r.db.QueryRow(ctx, findSQL, customerID).Scan(
+		&cust.CustomerID,
+		&cust.Name,
+		&cust.Address,
+		&cust.IsDelinquent,
+		&cust.Active,
+		&cust.LoanID,
+		&cust.CreatedAt,
+		&cust.UpdatedAt,
+	)
+	monitoring.RecordDBQuery("FindByID", queryStatus(err), time.Since(startTime))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, customer.ErrNotFound
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to find customer", slog.Int64("customerID", customerID), slog.Any("error", err))
+		return nil, fmt.Errorf("failed to find customer %d: %w", customerID, err)
+	}
+	return &cust, nil
+}
@@ -49,11 +49,12 @@ func TestCustomerRepositoryUpsert(t *testing.T) {
 		IsDelinquent: false,
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
+		Sequence:     5,
 	}
 
 	upsertSQL := `
-		INSERT INTO customers (id, name, address, is_delinquent, active, loan_id, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO customers (id, name, address, is_delinquent, active, loan_id, created_at, updated_at, sequence)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 		ON CONFLICT (id) DO UPDATE SET
 			name = EXCLUDED.name,
 			address = EXCLUDED.address,
@@ -61,8 +62,11 @@ func TestCustomerRepositoryUpsert(t *testing.T) {
 			active = EXCLUDED.active,
 			loan_id = EXCLUDED.loan_id,
 			-- created_at should not be updated on conflict
-			updated_at = EXCLUDED.updated_at
-		WHERE customers.updated_at < EXCLUDED.updated_at
+			updated_at = EXCLUDED.updated_at,
+			sequence = GREATEST(customers.sequence, EXCLUDED.sequence)
+		WHERE CASE WHEN customers.sequence > 0 AND EXCLUDED.sequence > 0
+			THEN customers.sequence < EXCLUDED.sequence
+			ELSE customers.updated_at < EXCLUDED.updated_at END
 		RETURNING (xmax = 0) AS is_insert;
 	`
 
@@ -77,6 +81,7 @@ func TestCustomerRepositoryUpsert(t *testing.T) {
 				customerTest.LoanID,
 				customerTest.CreatedAt,
 				customerTest.UpdatedAt,
+				customerTest.Sequence,
 			).WillReturnRows(pgxmock.NewRows([]string{"is_insert"}).
 			AddRow(true))
 
@@ -85,6 +90,18 @@ func TestCustomerRepositoryUpsert(t *testing.T) {
 		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
 	})
 
+	t.Run("skips a copy older than the stored one", func(t *testing.T) {
+		mockPool.ExpectQuery(regexp.QuoteMeta(upsertSQL)).
+			WithArgs(pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(),
+				pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), int64(4)).
+			WillReturnRows(pgxmock.NewRows([]string{"is_insert"}))
+
+		stale := *customerTest
+		stale.Sequence = 4
+		assert.NoError(t, repo.Upsert(ctx, &stale))
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
 	t.Run("failed upsert", func(t *testing.T) {
 		mockPool.ExpectQuery(regexp.QuoteMeta(upsertSQL)).WithArgs(
 			customerTest.CustomerID,
@@ -95,6 +112,7 @@ func TestCustomerRepositoryUpsert(t *testing.T) {
 			customerTest.LoanID,
 			customerTest.CreatedAt,
 			customerTest.UpdatedAt,
+			customerTest.Sequence,
 		).WillReturnError(context.DeadlineExceeded)
 
 		err := repo.Upsert(ctx, customerTest)
@@ -103,3 +121,33 @@ func TestCustomerRepositoryUpsert(t *testing.T) {
 		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
 	})
 }
+
+func TestCustomerRepositoryFindByID(t *testing.T) {
+	ctx, repo, mockPool := setupCustomerRepo(t)
+	defer mockPool.Close()
+
+	findSQL := `SELECT id, name, address, is_delinquent, active, loan_id, created_at, updated_at
+		FROM customers WHERE id = $1`
+	columns := []string{"id", "name", "address", "is_delinquent", "active", "loan_id", "created_at", "updated_at"}
+	now := time.Now()
+
+	t.Run("found", func(t *testing.T) {
+		mockPool.ExpectQuery(regexp.QuoteMeta(findSQL)).WithArgs(int64(1)).
+			WillReturnRows(pgxmock.NewRows(columns).AddRow(int64(1), "John Doe", "123 Main St", true, true, (*int64)(nil), now, now))
+
+		cust, err := repo.FindByID(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, "123 Main St", cust.Address)
+		assert.True(t, cust.IsDelinquent)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mockPool.ExpectQuery(regexp.QuoteMeta(findSQL)).WithArgs(int64(2)).
+			WillReturnRows(pgxmock.NewRows(columns))
+
+		_, err := repo.FindByID(ctx, 2)
+		assert.ErrorIs(t, err, customer.ErrNotFound)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+}
@@ -0,0 +1,145 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"notify-service/internal/domain/loan"
+	"notify-service/internal/infrastructure/monitoring"
+	"os"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type LoanRepository struct {
+	db     DBPool
+	logger *slog.Logger
+}
+
+var _ loan.Repository = (*LoanRepository)(nil)
+
+const loanColumns = `id, customer_id, principal_amount, term_weeks, amount_paid, status, status_changed_at, last_payment_at, created_at, updated_at`
+
+const createLoanSQL = `
+		INSERT INTO loans (id, customer_id, principal_amount, term_weeks, status, status_changed_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+		ON CONFLICT (id) DO NOTHING`
+
+const recordLoanPaymentSQL = `
+		UPDATE loans SET
+			amount_paid = amount_paid + $2,
+			last_payment_at = GREATEST(last_payment_at, $3),
+			updated_at = GREATEST(updated_at, $3)
+		WHERE id = $1
+		RETURNING ` + loanColumns
+
+// updateLoanStatusSQL keeps the stored status when the change is older than
+// the last one applied, so a late delinquency cannot reopen a paid off loan.
+const updateLoanStatusSQL = `
+		UPDATE loans SET
+			status = CASE WHEN status_changed_at <= $3 THEN $2 ELSE status END,
+			status_changed_at = GREATEST(status_changed_at, $3),
+			updated_at = GREATEST(updated_at, $3)
+		WHERE id = $1
+		RETURNING ` + loanColumns
+
+const findLoanSQL = `SELECT ` + loanColumns + ` FROM loans WHERE id = $1`
+
+func NewLoanRepository(db DBPool, logger *slog.Logger) *LoanRepository {
+	if db == nil {
+		panic("DBPool cannot be nil for LoanRepository")
+	}
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+		logger.Warn("Warning: No logger provided to NewLoanRepository, using default stderr handler")
+	}
+	return &LoanRepository{
+		db:     db,
+		logger: logger.With("component", "LoanRepository"),
+	}
+}
+
+func (r *LoanRepository) Create(ctx context.Context, l *loan.Loan) error {
+	startTime := time.Now()
+	_, err := r.db.Exec(ctx, createLoanSQL,
+		l.LoanID,
+		l.CustomerID,
+		l.PrincipalAmount,
+		l.TermWeeks,
+		string(l.Status),
+		l.StatusChangedAt,
+		l.CreatedAt,
+	)
+	monitoring.RecordDBQuery("CreateLoan", queryStatus(err), time.Since(startTime))
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to create loan", slog.Int64("loanID", l.LoanID), slog.Any("error", err))
+		return fmt.Errorf("failed to create loan %d: %w", l.LoanID, err)
+	}
+	return nil
+}
+
+func (r *LoanRepository) RecordPayment(ctx context.Context, loanID int64, amount float64, at time.Time) (*loan.Loan, error) {
+	startTime := time.Now()
+	l, err := scanLoan(r.db.QueryRow(ctx, recordLoanPaymentSQL, loanID, amount, at))
+	monitoring.RecordDBQuery("RecordLoanPayment", queryStatus(err), time.Since(startTime))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, loan.ErrNotFound
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to record loan payment", slog.Int64("loanID", loanID), slog.Any("error", err))
+		return nil, fmt.Errorf("failed to record payment on loan %d: %w", loanID, err)
+	}
+	return l, nil
+}
+
+func (r *LoanRepository) UpdateStatus(ctx context.Context, loanID int64, status loan.Status, at time.Time) (*loan.Loan, error) {
+	startTime := time.Now()
+	l, err := scanLoan(r.db.QueryRow(ctx, updateLoanStatusSQL, loanID, string(status), at))
+	monitoring.RecordDBQuery("UpdateLoanStatus", queryStatus(err), time.Since(startTime))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, loan.ErrNotFound
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to update loan status", slog.Int64("loanID", loanID), slog.Any("error", err))
+		return nil, fmt.Errorf("failed to update status of loan %d: %w", loanID, err)
+	}
+	return l, nil
+}
+
+func (r *LoanRepository) FindByID(ctx context.Context, loanID int64) (*loan.Loan, error) {
+	startTime := time.Now()
+	l, err := scanLoan(r.db.QueryRow(ctx, findLoanSQL, loanID))
+	monitoring.RecordDBQuery("FindLoanByID", queryStatus(err), time.Since(startTime))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, loan.ErrNotFound
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to find loan", slog.Int64("loanID", loanID), slog.Any("error", err))
+		return nil, fmt.Errorf("failed to find loan %d: %w", loanID, err)
+	}
+	return l, nil
+}
+
+func scanLoan(row pgx.Row) (*loan.Loan, error) {
+	var l loan.Loan
+	var status string
+	err := row.Scan(
+		&l.LoanID,
+		&l.CustomerID,
+		&l.PrincipalAmount,
+		&l.TermWeeks,
+		&l.AmountPaid,
+		&status,
+		&l.StatusChangedAt,
+		&l.LastPaymentAt,
+		&l.CreatedAt,
+		&l.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	l.Status = loan.Status(status)
+	return &l, nil
+}
@@ -0,0 +1,131 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"notify-service/internal/domain/loan"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupLoanRepo(t *testing.T) (context.Context, *LoanRepository, pgxmock.PgxPoolIface) {
+	t.Helper()
+	mockPool, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("failed to open a stub database connection: %v", err)
+	}
+	return context.Background(), NewLoanRepository(mockPool, logger), mockPool
+}
+
+func loanRows(at time.Time, status string, amountPaid float64) *pgxmock.Rows {
+	return pgxmock.NewRows([]string{"id", "customer_id", "principal_amount", "term_weeks", "amount_paid", "status",
+		"status_changed_at", "last_payment_at", "created_at", "updated_at"}).
+		AddRow(int64(3), int64(7), 5000000.0, 50, amountPaid, status, at, &at, at, at)
+}
+
+func TestLoanRepositoryCreate(t *testing.T) {
+	ctx, repo, mockPool := setupLoanRepo(t)
+	defer mockPool.Close()
+	at := time.Date(2025, 3, 4, 10, 0, 0, 0, time.UTC)
+	l := &loan.Loan{LoanID: 3, CustomerID: 7, PrincipalAmount: 5000000, TermWeeks: 50, Status: loan.StatusActive, StatusChangedAt: at, CreatedAt: at}
+
+	t.Run("inserts the loan", func(t *testing.T) {
+		mockPool.ExpectExec(regexp.QuoteMeta(createLoanSQL)).
+			WithArgs(int64(3), int64(7), 5000000.0, 50, "ACTIVE", at, at).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+		assert.NoError(t, repo.Create(ctx, l))
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("returns database errors", func(t *testing.T) {
+		mockPool.ExpectExec(regexp.QuoteMeta(createLoanSQL)).
+			WithArgs(int64(3), int64(7), 5000000.0, 50, "ACTIVE", at, at).
+			WillReturnError(errors.New("connection reset"))
+
+		assert.ErrorContains(t, repo.Create(ctx, l), "failed to create loan 3")
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+}
+
+func TestLoanRepositoryRecordPayment(t *testing.T) {
+	ctx, repo, mockPool := setupLoanRepo(t)
+	defer mockPool.Close()
+	at := time.Date(2025, 3, 11, 10, 0, 0, 0, time.UTC)
+
+	t.Run("returns the updated loan", func(t *testing.T) {
+		mockPool.ExpectQuery(regexp.QuoteMeta(recordLoanPaymentSQL)).
+			WithArgs(int64(3), 110000.0, at).
+			WillReturnRows(loanRows(at, "ACTIVE", 220000))
+
+		l, err := repo.RecordPayment(ctx, 3, 110000, at)
+
+		require.NoError(t, err)
+		assert.Equal(t, 220000.0, l.AmountPaid)
+		assert.Equal(t, loan.StatusActive, l.Status)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("reports a loan that is not replicated yet", func(t *testing.T) {
+		mockPool.ExpectQuery(regexp.QuoteMeta(recordLoanPaymentSQL)).
+			WithArgs(int64(4), 110000.0, at).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}))
+
+		_, err := repo.RecordPayment(ctx, 4, 110000, at)
+
+		assert.ErrorIs(t, err, loan.ErrNotFound)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+}
+
+func TestLoanRepositoryUpdateStatus(t *testing.T) {
+	ctx, repo, mockPool := setupLoanRepo(t)
+	defer mockPool.Close()
+	at := time.Date(2025, 3, 25, 10, 0, 0, 0, time.UTC)
+
+	mockPool.ExpectQuery(regexp.QuoteMeta(updateLoanStatusSQL)).
+		WithArgs(int64(3), "PAID_OFF", at).
+		WillReturnRows(loanRows(at, "PAID_OFF", 5500000))
+
+	l, err := repo.UpdateStatus(ctx, 3, loan.StatusPaidOff, at)
+
+	require.NoError(t, err)
+	assert.Equal(t, loan.StatusPaidOff, l.Status)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestLoanRepositoryFindByID(t *testing.T) {
+	ctx, repo, mockPool := setupLoanRepo(t)
+	defer mockPool.Close()
+	at := time.Date(2025, 3, 4, 10, 0, 0, 0, time.UTC)
+
+	t.Run("returns the loan", func(t *testing.T) {
+		mockPool.ExpectQuery(regexp.QuoteMeta(findLoanSQL)).
+			WithArgs(int64(3)).
+			WillReturnRows(loanRows(at, "DELINQUENT", 0))
+
+		l, err := repo.FindByID(ctx, 3)
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), l.CustomerID)
+		assert.Equal(t, loan.StatusDelinquent, l.Status)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("reports a loan that is not replicated yet", func(t *testing.T) {
+		mockPool.ExpectQuery(regexp.QuoteMeta(findLoanSQL)).
+			WithArgs(int64(4)).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}))
+
+		_, err := repo.FindByID(ctx, 4)
+
+		assert.ErrorIs(t, err, loan.ErrNotFound)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+}
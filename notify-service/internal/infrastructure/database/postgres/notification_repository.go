@@ -0,0 +1,159 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"notify-service/internal/domain/notification"
+	"notify-service/internal/infrastructure/monitoring"
+	"os"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type NotificationRepository struct {
+	db     DBPool
+	logger *slog.Logger
+}
+
+var _ notification.Repository = (*NotificationRepository)(nil)
+
+const createNotificationSQL = `
+		INSERT INTO notifications (customer_id, event, channel, recipient, subject, body, status, error, sent_at, deliver_after)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, created_at`
+
+const listNotificationsByCustomerSQL = `
+		SELECT id, customer_id, event, channel, recipient, subject, body, status, error, sent_at, deliver_after, created_at
+		FROM notifications
+		WHERE customer_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2`
+
+const countSentNotificationsSQL = `
+		SELECT COUNT(*) FROM notifications
+		WHERE customer_id = $1 AND status = 'SENT' AND sent_at >= $2`
+
+// claimDeferredNotificationsSQL leases due rows the same way the retry store
+// does, so several instances can dispatch side by side.
+const claimDeferredNotificationsSQL = `
+		UPDATE notifications SET deliver_after = $2
+		WHERE id IN (
+			SELECT id FROM notifications
+			WHERE status = 'DEFERRED' AND deliver_after <= $1
+			ORDER BY deliver_after
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, customer_id, event, channel, recipient, subject, body, status, error, sent_at, deliver_after, created_at`
+
+const updateNotificationDeliverySQL = `
+		UPDATE notifications SET status = $2, error = $3, sent_at = $4, deliver_after = $5
+		WHERE id = $1`
+
+func NewNotificationRepository(db DBPool, logger *slog.Logger) *NotificationRepository {
+	if db == nil {
+		panic("DBPool cannot be nil for NotificationRepository")
+	}
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+		logger.Warn("Warning: No logger provided to NewNotificationRepository, using default stderr handler")
+	}
+	return &NotificationRepository{
+		db:     db,
+		logger: logger.With("component", "NotificationRepository"),
+	}
+}
+
+func (r *NotificationRepository) Create(ctx context.Context, n *notification.Notification) error {
+	startTime := time.Now()
+	err := r.db.QueryRow(ctx, createNotificationSQL,
+		n.CustomerID, n.Event, n.Channel, n.Recipient, n.Subject, n.Body, string(n.Status), n.Error, n.SentAt, n.DeliverAfter,
+	).Scan(&n.ID, &n.CreatedAt)
+	monitoring.RecordDBQuery("CreateNotification", queryStatus(err), time.Since(startTime))
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to record notification", slog.Int64("customerID", n.CustomerID), slog.Any("error", err))
+		return fmt.Errorf("failed to record notification for customer %d: %w", n.CustomerID, err)
+	}
+	return nil
+}
+
+func (r *NotificationRepository) ListByCustomer(ctx context.Context, customerID int64, limit int) ([]notification.Notification, error) {
+	startTime := time.Now()
+	rows, err := r.db.Query(ctx, listNotificationsByCustomerSQL, customerID, limit)
+	if err != nil {
+		monitoring.RecordDBQuery("ListNotificationsByCustomer", "error", time.Since(startTime))
+		r.logger.ErrorContext(ctx, "Failed to list notifications", slog.Int64("customerID", customerID), slog.Any("error", err))
+		return nil, fmt.Errorf("failed to list notifications: %w", err)
+	}
+	defer rows.Close()
+
+	notifications, err := scanNotifications(rows)
+	if err != nil {
+		monitoring.RecordDBQuery("ListNotificationsByCustomer", "error", time.Since(startTime))
+		return nil, err
+	}
+
+	monitoring.RecordDBQuery("ListNotificationsByCustomer", "success", time.Since(startTime))
+	return notifications, nil
+}
+
+func (r *NotificationRepository) CountSentSince(ctx context.Context, customerID int64, since time.Time) (int, error) {
+	startTime := time.Now()
+	var count int
+	err := r.db.QueryRow(ctx, countSentNotificationsSQL, customerID, since).Scan(&count)
+	monitoring.RecordDBQuery("CountSentNotifications", queryStatus(err), time.Since(startTime))
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to count sent notifications", slog.Int64("customerID", customerID), slog.Any("error", err))
+		return 0, fmt.Errorf("failed to count sent notifications: %w", err)
+	}
+	return count, nil
+}
+
+func (r *NotificationRepository) ClaimDeferred(ctx context.Context, now, leaseUntil time.Time, limit int) ([]notification.Notification, error) {
+	startTime := time.Now()
+	rows, err := r.db.Query(ctx, claimDeferredNotificationsSQL, now, leaseUntil, limit)
+	if err != nil {
+		monitoring.RecordDBQuery("ClaimDeferredNotifications", "error", time.Since(startTime))
+		r.logger.ErrorContext(ctx, "Failed to claim deferred notifications", slog.Any("error", err))
+		return nil, fmt.Errorf("failed to claim deferred notifications: %w", err)
+	}
+	defer rows.Close()
+
+	notifications, err := scanNotifications(rows)
+	monitoring.RecordDBQuery("ClaimDeferredNotifications", queryStatus(err), time.Since(startTime))
+	if err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+func (r *NotificationRepository) UpdateDelivery(ctx context.Context, n *notification.Notification) error {
+	startTime := time.Now()
+	_, err := r.db.Exec(ctx, updateNotificationDeliverySQL, n.ID, string(n.Status), n.Error, n.SentAt, n.DeliverAfter)
+	monitoring.RecordDBQuery("UpdateNotificationDelivery", queryStatus(err), time.Since(startTime))
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to update notification", slog.Int64("notificationID", n.ID), slog.Any("error", err))
+		return fmt.Errorf("failed to update notification %d: %w", n.ID, err)
+	}
+	return nil
+}
+
+func scanNotifications(rows pgx.Rows) ([]notification.Notification, error) {
+	notifications := make([]notification.Notification, 0)
+	for rows.Next() {
+		var n notification.Notification
+		var status string
+		if err := rows.Scan(&n.ID, &n.CustomerID, &n.Event, &n.Channel, &n.Recipient, &n.Subject, &n.Body,
+			&status, &n.Error, &n.SentAt, &n.DeliverAfter, &n.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		n.Status = notification.Status(status)
+		notifications = append(notifications, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read notifications: %w", err)
+	}
+	return notifications, nil
+}
@@ -0,0 +1,137 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"notify-service/internal/domain/notification"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupNotificationRepo(t *testing.T) (context.Context, *NotificationRepository, pgxmock.PgxPoolIface) {
+	t.Helper()
+	mockPool, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("failed to open a stub database connection: %v", err)
+	}
+	return context.Background(), NewNotificationRepository(mockPool, logger), mockPool
+}
+
+func TestNotificationRepositoryCreate(t *testing.T) {
+	ctx, repo, mockPool := setupNotificationRepo(t)
+	defer mockPool.Close()
+
+	now := time.Now()
+	n := &notification.Notification{
+		CustomerID: 7,
+		Event:      notification.EventDelinquencyNotice,
+		Channel:    "log",
+		Recipient:  "123 Main St",
+		Status:     notification.StatusSent,
+		SentAt:     &now,
+	}
+	mockPool.ExpectQuery(regexp.QuoteMeta(createNotificationSQL)).
+		WithArgs(int64(7), notification.EventDelinquencyNotice, "log", "123 Main St", "", "", "SENT", "", &now, (*time.Time)(nil)).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "created_at"}).AddRow(int64(11), now))
+
+	err := repo.Create(ctx, n)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(11), n.ID)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+var notificationColumns = []string{"id", "customer_id", "event", "channel", "recipient", "subject", "body", "status", "error", "sent_at", "deliver_after", "created_at"}
+
+func TestNotificationRepositoryListByCustomer(t *testing.T) {
+	ctx, repo, mockPool := setupNotificationRepo(t)
+	defer mockPool.Close()
+
+	now := time.Now()
+
+	t.Run("returns rows", func(t *testing.T) {
+		mockPool.ExpectQuery(regexp.QuoteMeta(listNotificationsByCustomerSQL)).
+			WithArgs(int64(7), 50).
+			WillReturnRows(pgxmock.NewRows(notificationColumns).
+				AddRow(int64(2), int64(7), notification.EventDelinquencyNotice, "log", "123 Main St", "", "", "FAILED", "gateway timeout", (*time.Time)(nil), (*time.Time)(nil), now).
+				AddRow(int64(1), int64(7), notification.EventDelinquencyNotice, "log", "123 Main St", "", "", "SENT", "", &now, (*time.Time)(nil), now))
+
+		notifications, err := repo.ListByCustomer(ctx, 7, 50)
+
+		require.NoError(t, err)
+		require.Len(t, notifications, 2)
+		assert.Equal(t, notification.StatusFailed, notifications[0].Status)
+		assert.Nil(t, notifications[0].SentAt)
+		assert.NotNil(t, notifications[1].SentAt)
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("query error", func(t *testing.T) {
+		mockPool.ExpectQuery(regexp.QuoteMeta(listNotificationsByCustomerSQL)).
+			WithArgs(int64(7), 50).
+			WillReturnError(errors.New("connection reset"))
+
+		_, err := repo.ListByCustomer(ctx, 7, 50)
+
+		assert.ErrorContains(t, err, "connection reset")
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+}
+
+func TestNotificationRepositoryCountSentSince(t *testing.T) {
+	ctx, repo, mockPool := setupNotificationRepo(t)
+	defer mockPool.Close()
+
+	since := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	mockPool.ExpectQuery(regexp.QuoteMeta(countSentNotificationsSQL)).
+		WithArgs(int64(7), since).
+		WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(3))
+
+	count, err := repo.CountSentSince(ctx, 7, since)
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestNotificationRepositoryClaimDeferred(t *testing.T) {
+	ctx, repo, mockPool := setupNotificationRepo(t)
+	defer mockPool.Close()
+
+	now := time.Now()
+	lease := now.Add(5 * time.Minute)
+	mockPool.ExpectQuery(regexp.QuoteMeta(claimDeferredNotificationsSQL)).
+		WithArgs(now, lease, 10).
+		WillReturnRows(pgxmock.NewRows(notificationColumns).
+			AddRow(int64(4), int64(7), notification.EventDelinquencyNotice, "sms", "+6281234", "Overdue", "Please pay", "DEFERRED", "", (*time.Time)(nil), &lease, now))
+
+	due, err := repo.ClaimDeferred(ctx, now, lease, 10)
+
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	assert.Equal(t, notification.StatusDeferred, due[0].Status)
+	assert.Equal(t, "Please pay", due[0].Body)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestNotificationRepositoryUpdateDelivery(t *testing.T) {
+	ctx, repo, mockPool := setupNotificationRepo(t)
+	defer mockPool.Close()
+
+	now := time.Now()
+	n := &notification.Notification{ID: 4, Status: notification.StatusSent, SentAt: &now}
+	mockPool.ExpectExec(regexp.QuoteMeta(updateNotificationDeliverySQL)).
+		WithArgs(int64(4), "SENT", "", &now, (*time.Time)(nil)).
+		WillReturnError(errors.New("connection reset"))
+
+	err := repo.UpdateDelivery(ctx, n)
+
+	assert.ErrorContains(t, err, "failed to update notification 4")
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
@@ -0,0 +1,62 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"notify-service/internal/domain/idempotency"
+	"notify-service/internal/infrastructure/monitoring"
+	"os"
+	"time"
+)
+
+type ProcessedEventRepository struct {
+	db     DBPool
+	logger *slog.Logger
+}
+
+var _ idempotency.Repository = (*ProcessedEventRepository)(nil)
+
+const isEventProcessedSQL = `SELECT EXISTS (SELECT 1 FROM processed_events WHERE event_id = $1)`
+
+const markEventProcessedSQL = `
+		INSERT INTO processed_events (event_id, routing_key)
+		VALUES ($1, $2)
+		ON CONFLICT (event_id) DO NOTHING`
+
+func NewProcessedEventRepository(db DBPool, logger *slog.Logger) *ProcessedEventRepository {
+	if db == nil {
+		panic("DBPool cannot be nil for ProcessedEventRepository")
+	}
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+		logger.Warn("Warning: No logger provided to NewProcessedEventRepository, using default stderr handler")
+	}
+	return &ProcessedEventRepository{
+		db:     db,
+		logger: logger.With("component", "ProcessedEventRepository"),
+	}
+}
+
+func (r *ProcessedEventRepository) IsProcessed(ctx context.Context, eventID string) (bool, error) {
+	startTime := time.Now()
+	var processed bool
+	err := r.db.QueryRow(ctx, isEventProcessedSQL, eventID).Scan(&processed)
+	monitoring.RecordDBQuery("IsEventProcessed", queryStatus(err), time.Since(startTime))
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to look up processed event", slog.String("eventID", eventID), slog.Any("error", err))
+		return false, fmt.Errorf("failed to look up processed event %s: %w", eventID, err)
+	}
+	return processed, nil
+}
+
+func (r *ProcessedEventRepository) MarkProcessed(ctx context.Context, eventID, routingKey string) error {
+	startTime := time.Now()
+	_, err := r.db.Exec(ctx, markEventProcessedSQL, eventID, routingKey)
+	monitoring.RecordDBQuery("MarkEventProcessed", queryStatus(err), time.Since(startTime))
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to mark event processed", slog.String("eventID", eventID), slog.Any("error", err))
+		return fmt.Errorf("failed to mark event %s processed: %w", eventID, err)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupProcessedEventRepo(t *testing.T) (context.Context, *ProcessedEventRepository, pgxmock.PgxPoolIface) {
+	t.Helper()
+	mockPool, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("failed to open a stub database connection: %v", err)
+	}
+	return context.Background(), NewProcessedEventRepository(mockPool, logger), mockPool
+}
+
+func TestProcessedEventRepositoryIsProcessed(t *testing.T) {
+	ctx, repo, mockPool := setupProcessedEventRepo(t)
+	defer mockPool.Close()
+
+	mockPool.ExpectQuery(regexp.QuoteMeta(isEventProcessedSQL)).
+		WithArgs("evt-1").
+		WillReturnRows(pgxmock.NewRows([]string{"exists"}).AddRow(true))
+
+	processed, err := repo.IsProcessed(ctx, "evt-1")
+
+	require.NoError(t, err)
+	assert.True(t, processed)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestProcessedEventRepositoryMarkProcessed(t *testing.T) {
+	ctx, repo, mockPool := setupProcessedEventRepo(t)
+	defer mockPool.Close()
+
+	t.Run("inserts the event", func(t *testing.T) {
+		mockPool.ExpectExec(regexp.QuoteMeta(markEventProcessedSQL)).
+			WithArgs("evt-1", "customer.created").
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+		assert.NoError(t, repo.MarkProcessed(ctx, "evt-1", "customer.created"))
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+
+	t.Run("returns database errors", func(t *testing.T) {
+		mockPool.ExpectExec(regexp.QuoteMeta(markEventProcessedSQL)).
+			WithArgs("evt-2", "customer.created").
+			WillReturnError(errors.New("connection reset"))
+
+		assert.ErrorContains(t, repo.MarkProcessed(ctx, "evt-2", "customer.created"), "failed to mark event evt-2 processed")
+		assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+	})
+}
@@ -0,0 +1,123 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"notify-service/internal/domain/retry"
+	"notify-service/internal/infrastructure/monitoring"
+	"os"
+	"time"
+)
+
+type RetryRepository struct {
+	db     DBPool
+	logger *slog.Logger
+}
+
+var _ retry.Repository = (*RetryRepository)(nil)
+
+const enqueueRetrySQL = `
+		INSERT INTO notify_retries (routing_key, body, attempts, last_error, next_attempt_at)
+		VALUES ($1, $2, 1, $3, $4)`
+
+// claimDueRetriesSQL leases due rows in a single statement. SKIP LOCKED lets
+// several service instances claim batches side by side.
+const claimDueRetriesSQL = `
+		UPDATE notify_retries SET next_attempt_at = $2, updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM notify_retries
+			WHERE next_attempt_at <= $1
+			ORDER BY next_attempt_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, routing_key, body, attempts, last_error, next_attempt_at, created_at, updated_at`
+
+const rescheduleRetrySQL = `
+		UPDATE notify_retries SET attempts = $2, last_error = $3, next_attempt_at = $4, updated_at = NOW()
+		WHERE id = $1`
+
+const deleteRetrySQL = `DELETE FROM notify_retries WHERE id = $1`
+
+func NewRetryRepository(db DBPool, logger *slog.Logger) *RetryRepository {
+	if db == nil {
+		panic("DBPool cannot be nil for RetryRepository")
+	}
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+		logger.Warn("Warning: No logger provided to NewRetryRepository, using default stderr handler")
+	}
+	return &RetryRepository{
+		db:     db,
+		logger: logger.With("component", "RetryRepository"),
+	}
+}
+
+func (r *RetryRepository) Enqueue(ctx context.Context, routingKey string, body []byte, lastError string, nextAttemptAt time.Time) error {
+	startTime := time.Now()
+	_, err := r.db.Exec(ctx, enqueueRetrySQL, routingKey, body, lastError, nextAttemptAt)
+	monitoring.RecordDBQuery("EnqueueRetry", queryStatus(err), time.Since(startTime))
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to enqueue retry", slog.String("routingKey", routingKey), slog.Any("error", err))
+		return fmt.Errorf("failed to enqueue retry for %s: %w", routingKey, err)
+	}
+	return nil
+}
+
+func (r *RetryRepository) ClaimDue(ctx context.Context, now, leaseUntil time.Time, limit int) ([]retry.Retry, error) {
+	startTime := time.Now()
+	rows, err := r.db.Query(ctx, claimDueRetriesSQL, now, leaseUntil, limit)
+	if err != nil {
+		monitoring.RecordDBQuery("ClaimDueRetries", "error", time.Since(startTime))
+		r.logger.ErrorContext(ctx, "Failed to claim due retries", slog.Any("error", err))
+		return nil, fmt.Errorf("failed to claim due retries: %w", err)
+	}
+	defer rows.Close()
+
+	retries := make([]retry.Retry, 0)
+	for rows.Next() {
+		var rt retry.Retry
+		if err := rows.Scan(&rt.ID, &rt.RoutingKey, &rt.Body, &rt.Attempts, &rt.LastError, &rt.NextAttemptAt, &rt.CreatedAt, &rt.UpdatedAt); err != nil {
+			monitoring.RecordDBQuery("ClaimDueRetries", "error", time.Since(startTime))
+			return nil, fmt.Errorf("failed to scan claimed retry: %w", err)
+		}
+		retries = append(retries, rt)
+	}
+	if err := rows.Err(); err != nil {
+		monitoring.RecordDBQuery("ClaimDueRetries", "error", time.Since(startTime))
+		return nil, fmt.Errorf("failed to read claimed retries: %w", err)
+	}
+
+	monitoring.RecordDBQuery("ClaimDueRetries", "success", time.Since(startTime))
+	return retries, nil
+}
+
+func (r *RetryRepository) Reschedule(ctx context.Context, id int64, attempts int, lastError string, nextAttemptAt *time.Time) error {
+	startTime := time.Now()
+	_, err := r.db.Exec(ctx, rescheduleRetrySQL, id, attempts, lastError, nextAttemptAt)
+	monitoring.RecordDBQuery("RescheduleRetry", queryStatus(err), time.Since(startTime))
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to reschedule retry", slog.Int64("retryID", id), slog.Any("error", err))
+		return fmt.Errorf("failed to reschedule retry %d: %w", id, err)
+	}
+	return nil
+}
+
+func (r *RetryRepository) Delete(ctx context.Context, id int64) error {
+	startTime := time.Now()
+	_, err := r.db.Exec(ctx, deleteRetrySQL, id)
+	monitoring.RecordDBQuery("DeleteRetry", queryStatus(err), time.Since(startTime))
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to delete retry", slog.Int64("retryID", id), slog.Any("error", err))
+		return fmt.Errorf("failed to delete retry %d: %w", id, err)
+	}
+	return nil
+}
+
+func queryStatus(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
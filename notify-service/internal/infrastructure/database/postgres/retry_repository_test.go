@@ -0,0 +1,89 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupRetryRepo(t *testing.T) (context.Context, *RetryRepository, pgxmock.PgxPoolIface) {
+	t.Helper()
+	mockPool, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("failed to open a stub database connection: %v", err)
+	}
+	return context.Background(), NewRetryRepository(mockPool, logger), mockPool
+}
+
+func TestRetryRepositoryEnqueue(t *testing.T) {
+	ctx, repo, mockPool := setupRetryRepo(t)
+	defer mockPool.Close()
+
+	next := time.Now().Add(time.Minute)
+	body := []byte(`{"payload":{"customerId":1}}`)
+	mockPool.ExpectExec(regexp.QuoteMeta(enqueueRetrySQL)).
+		WithArgs("customer.created", body, "connection refused", next).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+	err := repo.Enqueue(ctx, "customer.created", body, "connection refused", next)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestRetryRepositoryClaimDue(t *testing.T) {
+	ctx, repo, mockPool := setupRetryRepo(t)
+	defer mockPool.Close()
+
+	now := time.Now()
+	lease := now.Add(5 * time.Minute)
+	mockPool.ExpectQuery(regexp.QuoteMeta(claimDueRetriesSQL)).
+		WithArgs(now, lease, 10).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "routing_key", "body", "attempts", "last_error", "next_attempt_at", "created_at", "updated_at"}).
+			AddRow(int64(3), "customer.updated", []byte(`{}`), 2, "timeout", &lease, now, now))
+
+	retries, err := repo.ClaimDue(ctx, now, lease, 10)
+
+	require.NoError(t, err)
+	require.Len(t, retries, 1)
+	assert.Equal(t, int64(3), retries[0].ID)
+	assert.Equal(t, 2, retries[0].Attempts)
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
+
+func TestRetryRepositoryClaimDueError(t *testing.T) {
+	ctx, repo, mockPool := setupRetryRepo(t)
+	defer mockPool.Close()
+
+	now := time.Now()
+	mockPool.ExpectQuery(regexp.QuoteMeta(claimDueRetriesSQL)).
+		WithArgs(now, now, 10).
+		WillReturnError(errors.New("db down"))
+
+	retries, err := repo.ClaimDue(ctx, now, now, 10)
+
+	assert.Nil(t, retries)
+	assert.Error(t, err)
+}
+
+func TestRetryRepositoryRescheduleAndDelete(t *testing.T) {
+	ctx, repo, mockPool := setupRetryRepo(t)
+	defer mockPool.Close()
+
+	mockPool.ExpectExec(regexp.QuoteMeta(rescheduleRetrySQL)).
+		WithArgs(int64(3), 5, "still failing", (*time.Time)(nil)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mockPool.ExpectExec(regexp.QuoteMeta(deleteRetrySQL)).
+		WithArgs(int64(4)).
+		WillReturnResult(pgxmock.NewResult("DELETE", 1))
+
+	assert.NoError(t, repo.Reschedule(ctx, 3, 5, "still failing", nil))
+	assert.NoError(t, repo.Delete(ctx, 4))
+	assert.NoError(t, mockPool.ExpectationsWereMet(), pgxmockExpectationsNotMetMsg)
+}
@@ -0,0 +1,138 @@
+// Package errorreport sends failures to Sentry: every record logged at error
+// level, through Handler, and deliveries the consumer could not process.
+package errorreport
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"notify-service/internal/config"
+	"path"
+	"runtime/debug"
+	"time"
+)
+
+// Event is one failure to report. Tags are indexed by Sentry, such as the
+// routing key of a failed delivery.
+type Event struct {
+	Message string
+	Time    time.Time
+	Tags    map[string]string
+}
+
+type Reporter interface {
+	Report(ctx context.Context, event Event) error
+}
+
+// New returns a reporter for the Sentry project cfg.DSN names, or nil when
+// cfg has no DSN. The reports go through client; nil means one with
+// cfg.Timeout.
+func New(cfg config.ErrorReportingConfig, client *http.Client) (Reporter, error) {
+	if cfg.DSN == "" {
+		return nil, nil
+	}
+	if client == nil {
+		client = &http.Client{Timeout: cfg.Timeout}
+	}
+	release := cfg.Release
+	if release == "" {
+		release = buildRevision()
+	}
+	reporter, err := newSentryReporter(cfg.DSN, cfg.Environment, release, client)
+	if err != nil {
+		return nil, err
+	}
+	return reporter, nil
+}
+
+// buildRevision is the VCS revision the binary was built from, or its
+// module version, or empty when neither was recorded.
+func buildRevision() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" {
+			return setting.Value
+		}
+	}
+	if info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return ""
+}
+
+// sentryReporter posts events to the store endpoint of a DSN such as
+// https://<key>@o1.ingest.sentry.io/<project>.
+type sentryReporter struct {
+	storeURL    string
+	auth        string
+	environment string
+	release     string
+	client      *http.Client
+}
+
+func newSentryReporter(dsn, environment, release string, client *http.Client) (*sentryReporter, error) {
+	u, err := url.Parse(dsn)
+	if err != nil || u.Host == "" || u.User.Username() == "" {
+		return nil, fmt.Errorf("invalid Sentry DSN, want https://<key>@<host>/<project>")
+	}
+	project := path.Base(u.Path)
+	if project == "." || project == "/" {
+		return nil, fmt.Errorf("invalid Sentry DSN: no project ID")
+	}
+	store := url.URL{Scheme: u.Scheme, Host: u.Host, Path: path.Join(path.Dir(u.Path), "api", project, "store") + "/"}
+	return &sentryReporter{
+		storeURL:    store.String(),
+		auth:        fmt.Sprintf("Sentry sentry_version=7, sentry_client=notify-service/1.0, sentry_key=%s", u.User.Username()),
+		environment: environment,
+		release:     release,
+		client:      client,
+	}, nil
+}
+
+func (s *sentryReporter) Report(ctx context.Context, event Event) error {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	body := map[string]any{
+		"event_id":    hex.EncodeToString(id),
+		"timestamp":   event.Time.UTC().Format("2006-01-02T15:04:05.000Z"),
+		"level":       "error",
+		"platform":    "go",
+		"logger":      "notify-service",
+		"environment": s.environment,
+		"message":     map[string]string{"formatted": event.Message},
+		"tags":        event.Tags,
+	}
+	if s.release != "" {
+		body["release"] = s.release
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("encode error report: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.storeURL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("build error report request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", s.auth)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("send error report: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("send error report: Sentry answered %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package errorreport
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"notify-service/internal/config"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	reporter, err := New(config.ErrorReportingConfig{}, nil)
+	require.NoError(t, err)
+	assert.Nil(t, reporter)
+
+	for _, dsn := range []string{"not a dsn", "https://o1.ingest.sentry.io/42", "https://key@o1.ingest.sentry.io/"} {
+		reporter, err := New(config.ErrorReportingConfig{DSN: dsn}, nil)
+		assert.Error(t, err, dsn)
+		assert.Nil(t, reporter, dsn)
+	}
+}
+
+func TestSentryReporter(t *testing.T) {
+	var path, auth string
+	var body map[string]any
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, auth = r.URL.Path, r.Header.Get("X-Sentry-Auth")
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	dsn := strings.Replace(server.URL, "://", "://public-key@", 1) + "/42"
+	reporter, err := New(config.ErrorReportingConfig{DSN: dsn, Environment: "staging", Release: "v1.4.0"}, server.Client())
+	require.NoError(t, err)
+
+	event := Event{Message: "Processing failed: timeout", Time: time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC), Tags: map[string]string{"routingKey": "loan.created"}}
+	require.NoError(t, reporter.Report(context.Background(), event))
+
+	assert.Equal(t, "/api/42/store/", path)
+	assert.Contains(t, auth, "sentry_key=public-key")
+	assert.Equal(t, "staging", body["environment"])
+	assert.Equal(t, "v1.4.0", body["release"])
+	assert.Equal(t, "error", body["level"])
+	assert.Len(t, body["event_id"], 32)
+	assert.Equal(t, map[string]any{"formatted": event.Message}, body["message"])
+	assert.Equal(t, map[string]any{"routingKey": "loan.created"}, body["tags"])
+
+	status = http.StatusTooManyRequests
+	assert.ErrorContains(t, reporter.Report(context.Background(), event), "429")
+}
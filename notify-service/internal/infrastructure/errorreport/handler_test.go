@@ -0,0 +1,89 @@
+package errorreport
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// syncBuffer is written by the reports that fail in the background.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+type reporterFunc func(context.Context, Event) error
+
+func (f reporterFunc) Report(ctx context.Context, event Event) error { return f(ctx, event) }
+
+func TestHandler(t *testing.T) {
+	var logs syncBuffer
+	reported := make(chan Event, 4)
+	var fail atomic.Bool
+	logger := slog.New(NewHandler(slog.NewJSONHandler(&logs, nil), reporterFunc(func(_ context.Context, event Event) error {
+		reported <- event
+		if fail.Load() {
+			return errors.New("sentry is down")
+		}
+		return nil
+	})))
+	next := func(t *testing.T) Event {
+		t.Helper()
+		select {
+		case event := <-reported:
+			return event
+		case <-time.After(time.Second):
+			t.Fatal("nothing was reported")
+			return Event{}
+		}
+	}
+
+	t.Run("reports error records with their attributes as tags", func(t *testing.T) {
+		logger.With("routingKey", "loan.delinquent").
+			Error("Giving up on retry, parked for inspection", slog.Any("error", errors.New("deadlock")), slog.Int64("retryID", 7))
+
+		event := next(t)
+		assert.Equal(t, "Giving up on retry, parked for inspection: deadlock", event.Message)
+		assert.Equal(t, map[string]string{"routingKey": "loan.delinquent", "retryID": "7"}, event.Tags)
+		assert.Contains(t, logs.String(), "Giving up on retry")
+	})
+
+	t.Run("skips lower levels and records reported already", func(t *testing.T) {
+		logger.Warn("Retry failed, rescheduled")
+		logger.ErrorContext(MarkReported(context.Background()), "Processing failed")
+		logger.Error("Sent")
+
+		assert.Equal(t, "Sent", next(t).Message)
+	})
+
+	t.Run("logs a failed report as a warning", func(t *testing.T) {
+		fail.Store(true)
+		defer fail.Store(false)
+
+		logger.Error("Failed to store retry")
+		next(t)
+
+		require.Eventually(t, func() bool { return strings.Contains(logs.String(), "Failed to report error") }, time.Second, 5*time.Millisecond)
+	})
+}
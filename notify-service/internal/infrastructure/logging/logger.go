@@ -3,13 +3,16 @@ package logging
 import (
 	"log/slog"
 	"notify-service/internal/config"
+	"notify-service/internal/infrastructure/errorreport"
 	"os"
 	"strings"
 
 	"github.com/go-chi/traceid"
 )
 
-func NewLogger(cfg config.LoggerConfig) *slog.Logger {
+// NewLogger returns the JSON logger and makes it the default. Records at
+// error level also go to reporter unless that is nil.
+func NewLogger(cfg config.LoggerConfig, reporter errorreport.Reporter) *slog.Logger {
 	var level slog.Level
 	switch strings.ToLower(cfg.Level) {
 	case "debug":
@@ -28,7 +31,10 @@ func NewLogger(cfg config.LoggerConfig) *slog.Logger {
 		Level:     level,
 		AddSource: level == slog.LevelDebug,
 	}
-	handler := slog.NewJSONHandler(os.Stdout, opts)
+	var handler slog.Handler = slog.NewJSONHandler(os.Stdout, opts)
+	if reporter != nil {
+		handler = errorreport.NewHandler(handler, reporter)
+	}
 	handler = traceid.LogHandler(handler)
 	logger := slog.New(handler)
 	slog.SetDefault(logger)
@@ -20,6 +20,12 @@ type BusinessMetrics struct {
 	ConsumerCreatedTotal prometheus.Counter
 	CostumerCreatedTotal prometheus.Counter
 	CostumerUpdatedTotal prometheus.Counter
+	RetriesTotal         *prometheus.CounterVec
+	DuplicateEventsTotal prometheus.Counter
+	DeliveriesInFlight   prometheus.Gauge
+	NotificationsTotal   *prometheus.CounterVec
+	ProviderDeliveries   *prometheus.CounterVec
+	ProviderHealthy      *prometheus.GaugeVec
 }
 
 var (
@@ -71,6 +77,46 @@ var (
 				Help: "Total number of costumer successfully updated.",
 			},
 		),
+		RetriesTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "notify_service_retries_total",
+				Help: "Retry store transitions by outcome: enqueued, succeeded, rescheduled or parked.",
+			},
+			[]string{"outcome"},
+		),
+		DuplicateEventsTotal: promauto.NewCounter(
+			prometheus.CounterOpts{
+				Name: "notify_service_duplicate_events_total",
+				Help: "Total number of redelivered events skipped because they were already processed.",
+			},
+		),
+		DeliveriesInFlight: promauto.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "notify_service_deliveries_in_flight",
+				Help: "Number of RabbitMQ deliveries being handled by consumer workers.",
+			},
+		),
+		NotificationsTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "notify_service_notifications_total",
+				Help: "Notification attempts by event, channel and outcome: SENT, FAILED or DEFERRED.",
+			},
+			[]string{"event", "channel", "status"},
+		),
+		ProviderDeliveries: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "notify_service_provider_deliveries_total",
+				Help: "Delivery attempts by channel, provider and outcome: sent, failed or rejected.",
+			},
+			[]string{"channel", "provider", "status"},
+		),
+		ProviderHealthy: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "notify_service_provider_healthy",
+				Help: "1 while a delivery provider is in use, 0 while failover skips it.",
+			},
+			[]string{"channel", "provider"},
+		),
 	}
 )
 
@@ -94,3 +140,35 @@ func RecordCostumer(actionInsert bool) {
 		Business.CostumerUpdatedTotal.Inc()
 	}
 }
+
+func RecordRetry(outcome string) {
+	Business.RetriesTotal.WithLabelValues(outcome).Inc()
+}
+
+func RecordDuplicateEvent() {
+	Business.DuplicateEventsTotal.Inc()
+}
+
+func RecordDeliveryStarted() {
+	Business.DeliveriesInFlight.Inc()
+}
+
+func RecordDeliveryFinished() {
+	Business.DeliveriesInFlight.Dec()
+}
+
+func RecordNotification(event, channel, status string) {
+	Business.NotificationsTotal.WithLabelValues(event, channel, status).Inc()
+}
+
+func RecordProviderDelivery(channel, provider, status string) {
+	Business.ProviderDeliveries.WithLabelValues(channel, provider, status).Inc()
+}
+
+func SetProviderHealthy(channel, provider string, healthy bool) {
+	value := 0.0
+	if healthy {
+		value = 1
+	}
+	Business.ProviderHealthy.WithLabelValues(channel, provider).Set(value)
+}
@@ -0,0 +1,213 @@
+package sender
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"notify-service/internal/domain/notification"
+	"strings"
+	"time"
+)
+
+const (
+	ProviderSNS = "sns"
+	ProviderSES = "ses"
+)
+
+// AWSConfig holds the credentials and region of an IAM user allowed to call
+// SNS Publish or SES SendEmail. Endpoint overrides the regional endpoint,
+// for example to reach LocalStack.
+type AWSConfig struct {
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	Endpoint        string
+	Timeout         time.Duration
+}
+
+func (c AWSConfig) validate(provider string) error {
+	if c.Region == "" || c.AccessKeyID == "" || c.SecretAccessKey == "" {
+		return fmt.Errorf("%s needs region, accessKeyId and secretAccessKey", provider)
+	}
+	return nil
+}
+
+func (c AWSConfig) endpoint(service string) string {
+	if c.Endpoint != "" {
+		return strings.TrimRight(c.Endpoint, "/")
+	}
+	return fmt.Sprintf("https://%s.%s.amazonaws.com", service, c.Region)
+}
+
+// awsClient signs its requests with Signature Version 4, which is all SNS and
+// SES need and saves pulling in the AWS SDK.
+type awsClient struct {
+	cfg     AWSConfig
+	service string
+	client  *http.Client
+	now     func() time.Time
+}
+
+func (c *awsClient) do(ctx context.Context, provider, endpoint, contentType string, body []byte, rejectCodes ...string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build %s request: %w", provider, err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	signV4(req, body, c.service, c.cfg.Region, c.cfg.AccessKeyID, c.cfg.SecretAccessKey, c.now())
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s request failed: %w", provider, err)
+	}
+	defer resp.Body.Close()
+	return checkResponse(provider, resp, rejectCodes...)
+}
+
+// SNSSender sends SMS through Amazon SNS. SenderID, when set, names the
+// sender in countries that support alphanumeric sender IDs.
+type SNSSender struct {
+	aws      awsClient
+	senderID string
+}
+
+var _ Provider = (*SNSSender)(nil)
+
+func NewSNSSender(cfg AWSConfig, senderID string) (*SNSSender, error) {
+	if err := cfg.validate(ProviderSNS); err != nil {
+		return nil, err
+	}
+	return &SNSSender{
+		aws:      awsClient{cfg: cfg, service: "sns", client: newHTTPClient(cfg.Timeout), now: time.Now},
+		senderID: senderID,
+	}, nil
+}
+
+func (s *SNSSender) Name() string { return ProviderSNS }
+
+func (s *SNSSender) Send(ctx context.Context, msg notification.Message) error {
+	form := url.Values{
+		"Action":      {"Publish"},
+		"Version":     {"2010-03-31"},
+		"PhoneNumber": {msg.Recipient},
+		"Message":     {msg.Body},
+	}
+	if s.senderID != "" {
+		form.Set("MessageAttributes.entry.1.Name", "AWS.SNS.SMS.SenderID")
+		form.Set("MessageAttributes.entry.1.Value.DataType", "String")
+		form.Set("MessageAttributes.entry.1.Value.StringValue", s.senderID)
+	}
+	return s.aws.do(ctx, ProviderSNS, s.aws.cfg.endpoint("sns")+"/", "application/x-www-form-urlencoded",
+		[]byte(form.Encode()), "InvalidParameter")
+}
+
+// SESSender sends plain text email through the Amazon SES v2 API. From must
+// be a verified identity.
+type SESSender struct {
+	aws  awsClient
+	from string
+}
+
+var _ Provider = (*SESSender)(nil)
+
+func NewSESSender(cfg AWSConfig, from string) (*SESSender, error) {
+	if err := cfg.validate(ProviderSES); err != nil {
+		return nil, err
+	}
+	if from == "" {
+		return nil, errors.New("ses needs from")
+	}
+	return &SESSender{
+		aws:  awsClient{cfg: cfg, service: "ses", client: newHTTPClient(cfg.Timeout), now: time.Now},
+		from: from,
+	}, nil
+}
+
+func (s *SESSender) Name() string { return ProviderSES }
+
+type sesContent struct {
+	Data string `json:"Data"`
+}
+
+type sesRequest struct {
+	FromEmailAddress string `json:"FromEmailAddress"`
+	Destination      struct {
+		ToAddresses []string `json:"ToAddresses"`
+	} `json:"Destination"`
+	Content struct {
+		Simple struct {
+			Subject sesContent `json:"Subject"`
+			Body    struct {
+				Text sesContent `json:"Text"`
+			} `json:"Body"`
+		} `json:"Simple"`
+	} `json:"Content"`
+}
+
+func (s *SESSender) Send(ctx context.Context, msg notification.Message) error {
+	var payload sesRequest
+	payload.FromEmailAddress = s.from
+	payload.Destination.ToAddresses = []string{msg.Recipient}
+	payload.Content.Simple.Subject.Data = msg.Subject
+	payload.Content.Simple.Body.Text.Data = msg.Body
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode ses request: %w", err)
+	}
+	return s.aws.do(ctx, ProviderSES, s.aws.cfg.endpoint("email")+"/v2/email/outbound-emails", "application/json",
+		body, "BadRequestException", "MessageRejected")
+}
+
+// signV4 adds the X-Amz-Date and Authorization headers of AWS Signature
+// Version 4, signing the content type, host and date.
+func signV4(req *http.Request, body []byte, service, region, accessKeyID, secretAccessKey string, at time.Time) {
+	amzDate := at.UTC().Format("20060102T150405Z")
+	day := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	const signedHeaders = "content-type;host;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		req.URL.Query().Encode(),
+		"content-type:" + req.Header.Get("Content-Type"),
+		"host:" + req.URL.Host,
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		sha256Hex(body),
+	}, "\n")
+	scope := day + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+secretAccessKey), day)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKeyID, scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,99 @@
+package sender
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"notify-service/internal/domain/notification"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The example request from the AWS Signature Version 4 documentation.
+func TestSignV4(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08", nil)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+
+	signV4(req, nil, "iam", "us-east-1", "AKIDEXAMPLE", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	assert.Equal(t, "20150830T123600Z", req.Header.Get("X-Amz-Date"))
+	assert.Equal(t, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, "+
+		"SignedHeaders=content-type;host;x-amz-date, "+
+		"Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7",
+		req.Header.Get("Authorization"))
+}
+
+func testAWSConfig(endpoint string) AWSConfig {
+	return AWSConfig{Region: "ap-southeast-1", AccessKeyID: "AKID", SecretAccessKey: "secret", Endpoint: endpoint}
+}
+
+func TestSNSSender(t *testing.T) {
+	status, answer := http.StatusOK, ""
+	var got *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		got = r
+		w.WriteHeader(status)
+		w.Write([]byte(answer))
+	}))
+	defer server.Close()
+
+	s, err := NewSNSSender(testAWSConfig(server.URL), "BILLING")
+	require.NoError(t, err)
+	msg := notification.Message{Recipient: "+6281234567890", Body: "Your payment is due"}
+
+	require.NoError(t, s.Send(context.Background(), msg))
+	assert.Equal(t, "Publish", got.PostForm.Get("Action"))
+	assert.Equal(t, "+6281234567890", got.PostForm.Get("PhoneNumber"))
+	assert.Equal(t, "Your payment is due", got.PostForm.Get("Message"))
+	assert.Equal(t, "BILLING", got.PostForm.Get("MessageAttributes.entry.1.Value.StringValue"))
+	assert.True(t, strings.HasPrefix(got.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/"))
+	assert.Contains(t, got.Header.Get("Authorization"), "/ap-southeast-1/sns/aws4_request")
+
+	status, answer = http.StatusBadRequest, "<Code>InvalidParameter</Code>"
+	assert.ErrorIs(t, s.Send(context.Background(), msg), ErrRejected)
+
+	status, answer = http.StatusBadRequest, "<Code>Throttling</Code>"
+	err = s.Send(context.Background(), msg)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrRejected)
+}
+
+func TestSESSender(t *testing.T) {
+	status, answer := http.StatusOK, `{"MessageId": "1"}`
+	var path string
+	var got sesRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		body, _ := io.ReadAll(r.Body)
+		require.NoError(t, json.Unmarshal(body, &got))
+		w.WriteHeader(status)
+		w.Write([]byte(answer))
+	}))
+	defer server.Close()
+
+	s, err := NewSESSender(testAWSConfig(server.URL), "billing@example.com")
+	require.NoError(t, err)
+	msg := notification.Message{Recipient: "budi@example.com", Subject: "Payment due", Body: "Your payment is due"}
+
+	require.NoError(t, s.Send(context.Background(), msg))
+	assert.Equal(t, "/v2/email/outbound-emails", path)
+	assert.Equal(t, "billing@example.com", got.FromEmailAddress)
+	assert.Equal(t, []string{"budi@example.com"}, got.Destination.ToAddresses)
+	assert.Equal(t, "Payment due", got.Content.Simple.Subject.Data)
+	assert.Equal(t, "Your payment is due", got.Content.Simple.Body.Text.Data)
+
+	status, answer = http.StatusBadRequest, `{"__type": "MessageRejected"}`
+	assert.ErrorIs(t, s.Send(context.Background(), msg), ErrRejected)
+
+	status, answer = http.StatusInternalServerError, ""
+	assert.NotErrorIs(t, s.Send(context.Background(), msg), ErrRejected)
+}
@@ -0,0 +1,152 @@
+package sender
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"notify-service/internal/domain/notification"
+	"notify-service/internal/infrastructure/monitoring"
+	"sync"
+	"time"
+)
+
+const (
+	ChannelSMS   = "sms"
+	ChannelEmail = "email"
+
+	DefaultFailureThreshold = 3
+	DefaultCooldown         = time.Minute
+)
+
+// ErrRejected marks a message the provider refused for what it is, such as
+// an invalid recipient. Another provider would refuse it too, so Failover
+// neither tries the next one nor counts it against the provider's health.
+var ErrRejected = errors.New("message rejected by provider")
+
+// Provider is a Sender backed by one delivery vendor.
+type Provider interface {
+	notification.Sender
+	Name() string
+}
+
+// FailoverConfig decides when a provider counts as down: after
+// FailureThreshold failures in a row it is skipped for Cooldown, then tried
+// again.
+type FailoverConfig struct {
+	FailureThreshold int
+	Cooldown         time.Duration
+}
+
+type providerHealth struct {
+	failures  int
+	downUntil time.Time
+}
+
+// Failover sends each message through the first healthy provider of a
+// channel and falls back to the next when it fails. Providers that are down
+// are still tried, last and in order, when every healthy one failed.
+type Failover struct {
+	channel   string
+	providers []Provider
+	cfg       FailoverConfig
+	logger    *slog.Logger
+	now       func() time.Time
+
+	mu     sync.Mutex
+	health []providerHealth
+}
+
+var _ notification.Sender = (*Failover)(nil)
+
+func NewFailover(channel string, providers []Provider, cfg FailoverConfig, logger *slog.Logger) *Failover {
+	if len(providers) == 0 {
+		panic("failover sender needs at least one provider")
+	}
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = DefaultFailureThreshold
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = DefaultCooldown
+	}
+	for _, p := range providers {
+		monitoring.SetProviderHealthy(channel, p.Name(), true)
+	}
+	return &Failover{
+		channel:   channel,
+		providers: providers,
+		cfg:       cfg,
+		logger:    logger.With("component", "FailoverSender", "channel", channel),
+		now:       time.Now,
+		health:    make([]providerHealth, len(providers)),
+	}
+}
+
+func (f *Failover) Send(ctx context.Context, msg notification.Message) error {
+	var errs []error
+	for _, i := range f.order() {
+		p := f.providers[i]
+		err := p.Send(ctx, msg)
+		switch {
+		case err == nil:
+			monitoring.RecordProviderDelivery(f.channel, p.Name(), "sent")
+			f.succeeded(i)
+			return nil
+		case errors.Is(err, ErrRejected):
+			monitoring.RecordProviderDelivery(f.channel, p.Name(), "rejected")
+			return err
+		}
+		monitoring.RecordProviderDelivery(f.channel, p.Name(), "failed")
+		f.failed(i)
+		errs = append(errs, fmt.Errorf("%s: %w", p.Name(), err))
+		if ctx.Err() != nil {
+			break
+		}
+		f.logger.WarnContext(ctx, "Provider failed, trying the next one", slog.String("provider", p.Name()), slog.Any("error", err))
+	}
+	return errors.Join(errs...)
+}
+
+// order lists the healthy providers first, then those that are down.
+func (f *Failover) order() []int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := f.now()
+	healthy := make([]int, 0, len(f.providers))
+	var down []int
+	for i, h := range f.health {
+		if now.Before(h.downUntil) {
+			down = append(down, i)
+		} else {
+			healthy = append(healthy, i)
+		}
+	}
+	return append(healthy, down...)
+}
+
+func (f *Failover) succeeded(i int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.health[i].failures >= f.cfg.FailureThreshold {
+		f.logger.Info("Provider recovered", slog.String("provider", f.providers[i].Name()))
+		monitoring.SetProviderHealthy(f.channel, f.providers[i].Name(), true)
+	}
+	f.health[i] = providerHealth{}
+}
+
+// failed takes a provider down once it reaches the threshold. A provider
+// that fails again right after its cooldown goes straight back down.
+func (f *Failover) failed(i int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	h := &f.health[i]
+	h.failures++
+	if h.failures < f.cfg.FailureThreshold {
+		return
+	}
+	if h.failures == f.cfg.FailureThreshold {
+		f.logger.Warn("Provider marked down", slog.String("provider", f.providers[i].Name()), slog.Duration("cooldown", f.cfg.Cooldown))
+		monitoring.SetProviderHealthy(f.channel, f.providers[i].Name(), false)
+	}
+	h.downUntil = f.now().Add(f.cfg.Cooldown)
+}
@@ -0,0 +1,101 @@
+package sender
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"notify-service/internal/domain/notification"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeProvider struct {
+	name  string
+	err   error
+	calls int
+}
+
+func (p *fakeProvider) Name() string { return p.name }
+
+func (p *fakeProvider) Send(context.Context, notification.Message) error {
+	p.calls++
+	return p.err
+}
+
+func newTestFailover(providers ...Provider) (*Failover, *time.Time) {
+	now := time.Date(2025, 4, 1, 9, 0, 0, 0, time.UTC)
+	f := NewFailover(ChannelSMS, providers, FailoverConfig{FailureThreshold: 2, Cooldown: time.Minute},
+		slog.New(slog.NewTextHandler(io.Discard, nil)))
+	f.now = func() time.Time { return now }
+	return f, &now
+}
+
+func TestFailoverUsesPrimaryWhileItWorks(t *testing.T) {
+	primary, fallback := &fakeProvider{name: "twilio"}, &fakeProvider{name: "sns"}
+	f, _ := newTestFailover(primary, fallback)
+
+	require.NoError(t, f.Send(context.Background(), notification.Message{}))
+	assert.Equal(t, 1, primary.calls)
+	assert.Equal(t, 0, fallback.calls)
+}
+
+func TestFailoverSkipsProviderThatIsDown(t *testing.T) {
+	primary, fallback := &fakeProvider{name: "twilio", err: errors.New("timeout")}, &fakeProvider{name: "sns"}
+	f, now := newTestFailover(primary, fallback)
+	ctx := context.Background()
+
+	require.NoError(t, f.Send(ctx, notification.Message{}))
+	require.NoError(t, f.Send(ctx, notification.Message{}))
+	assert.Equal(t, 2, primary.calls)
+	assert.Equal(t, 2, fallback.calls)
+
+	require.NoError(t, f.Send(ctx, notification.Message{}))
+	assert.Equal(t, 2, primary.calls, "primary is down after two failures")
+	assert.Equal(t, 3, fallback.calls)
+
+	primary.err = nil
+	*now = now.Add(time.Minute)
+	require.NoError(t, f.Send(ctx, notification.Message{}))
+	assert.Equal(t, 3, primary.calls, "primary is tried again after the cooldown")
+	assert.Equal(t, 3, fallback.calls)
+}
+
+func TestFailoverTriesDownProvidersLast(t *testing.T) {
+	primary := &fakeProvider{name: "twilio", err: errors.New("timeout")}
+	fallback := &fakeProvider{name: "sns", err: errors.New("throttled")}
+	f, _ := newTestFailover(primary, fallback)
+	ctx := context.Background()
+
+	for range 2 {
+		assert.Error(t, f.Send(ctx, notification.Message{}))
+	}
+	primary.err = nil
+	require.NoError(t, f.Send(ctx, notification.Message{}), "both are down, so the primary is still tried first")
+	assert.Equal(t, 3, primary.calls)
+	assert.Equal(t, 2, fallback.calls)
+}
+
+func TestFailoverDoesNotRetryRejectedMessage(t *testing.T) {
+	primary := &fakeProvider{name: "twilio", err: fmt.Errorf("%w: invalid number", ErrRejected)}
+	fallback := &fakeProvider{name: "sns"}
+	f, _ := newTestFailover(primary, fallback)
+
+	for range 3 {
+		assert.ErrorIs(t, f.Send(context.Background(), notification.Message{}), ErrRejected)
+	}
+	assert.Equal(t, 3, primary.calls, "rejections do not take the provider down")
+	assert.Equal(t, 0, fallback.calls)
+}
+
+func TestFailoverReportsEveryProviderError(t *testing.T) {
+	f, _ := newTestFailover(&fakeProvider{name: "twilio", err: errors.New("timeout")}, &fakeProvider{name: "sns", err: errors.New("throttled")})
+
+	err := f.Send(context.Background(), notification.Message{})
+	assert.ErrorContains(t, err, "twilio: timeout")
+	assert.ErrorContains(t, err, "sns: throttled")
+}
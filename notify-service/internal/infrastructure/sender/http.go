@@ -0,0 +1,50 @@
+package sender
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout bounds one call to a provider's API.
+const DefaultTimeout = 10 * time.Second
+
+// maxErrorBody is how much of an error response is kept in the error.
+const maxErrorBody = 512
+
+func newHTTPClient(timeout time.Duration) *http.Client {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &http.Client{Timeout: timeout}
+}
+
+// checkResponse turns a non-2xx answer into an error. Client errors whose
+// body names one of rejectCodes, or any client error when none are given,
+// mean the message itself was refused and wrap ErrRejected. Bad credentials
+// and throttling are the provider's problem and fail over.
+func checkResponse(provider string, resp *http.Response, rejectCodes ...string) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	err := fmt.Errorf("%s answered %d: %s", provider, resp.StatusCode, strings.TrimSpace(string(body)))
+	switch resp.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests:
+		return err
+	}
+	if resp.StatusCode < 400 || resp.StatusCode >= 500 {
+		return err
+	}
+	if len(rejectCodes) == 0 {
+		return fmt.Errorf("%w: %w", ErrRejected, err)
+	}
+	for _, code := range rejectCodes {
+		if strings.Contains(string(body), code) {
+			return fmt.Errorf("%w: %w", ErrRejected, err)
+		}
+	}
+	return err
+}
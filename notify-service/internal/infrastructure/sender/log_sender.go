@@ -0,0 +1,30 @@
+package sender
+
+import (
+	"context"
+	"log/slog"
+	"notify-service/internal/domain/notification"
+)
+
+const ChannelLog = "log"
+
+// LogSender writes messages to the service log instead of delivering them.
+// It is the default channel and needs no provider.
+type LogSender struct {
+	logger *slog.Logger
+}
+
+var _ notification.Sender = (*LogSender)(nil)
+
+func NewLogSender(logger *slog.Logger) *LogSender {
+	return &LogSender{logger: logger.With("component", "LogSender")}
+}
+
+func (s *LogSender) Send(ctx context.Context, msg notification.Message) error {
+	s.logger.InfoContext(ctx, "Delivering notification",
+		slog.String("channel", msg.Channel),
+		slog.String("recipient", msg.Recipient),
+		slog.String("subject", msg.Subject),
+		slog.String("body", msg.Body))
+	return nil
+}
@@ -0,0 +1,68 @@
+package sender
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"notify-service/internal/domain/notification"
+	"strings"
+	"time"
+)
+
+const (
+	ProviderTwilio = "twilio"
+
+	twilioBaseURL = "https://api.twilio.com"
+)
+
+// TwilioConfig holds the account credentials and the sending number.
+// BaseURL is only set to reach a stand-in for the API.
+type TwilioConfig struct {
+	AccountSID string
+	AuthToken  string
+	From       string
+	BaseURL    string
+	Timeout    time.Duration
+}
+
+// TwilioSender sends SMS through Twilio's Messages API.
+type TwilioSender struct {
+	cfg    TwilioConfig
+	client *http.Client
+}
+
+var _ Provider = (*TwilioSender)(nil)
+
+func NewTwilioSender(cfg TwilioConfig) (*TwilioSender, error) {
+	if cfg.AccountSID == "" || cfg.AuthToken == "" || cfg.From == "" {
+		return nil, errors.New("twilio needs accountSid, authToken and from")
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = twilioBaseURL
+	}
+	return &TwilioSender{cfg: cfg, client: newHTTPClient(cfg.Timeout)}, nil
+}
+
+func (s *TwilioSender) Name() string { return ProviderTwilio }
+
+// Send rejects the message on a 400, which Twilio answers for invalid and
+// unreachable numbers.
+func (s *TwilioSender) Send(ctx context.Context, msg notification.Message) error {
+	form := url.Values{"To": {msg.Recipient}, "From": {s.cfg.From}, "Body": {msg.Body}}
+	endpoint := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", strings.TrimRight(s.cfg.BaseURL, "/"), url.PathEscape(s.cfg.AccountSID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to build twilio request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(s.cfg.AccountSID, s.cfg.AuthToken)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("twilio request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	return checkResponse(ProviderTwilio, resp)
+}
@@ -0,0 +1,52 @@
+package sender
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"notify-service/internal/domain/notification"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTwilioSender(t *testing.T) {
+	status := http.StatusCreated
+	var got *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		got = r
+		w.WriteHeader(status)
+		w.Write([]byte(`{"code": 21211, "message": "Invalid 'To' Phone Number"}`))
+	}))
+	defer server.Close()
+
+	s, err := NewTwilioSender(TwilioConfig{AccountSID: "AC123", AuthToken: "secret", From: "+15005550006", BaseURL: server.URL})
+	require.NoError(t, err)
+	msg := notification.Message{Recipient: "+6281234567890", Body: "Your payment is due"}
+
+	require.NoError(t, s.Send(context.Background(), msg))
+	assert.Equal(t, "/2010-04-01/Accounts/AC123/Messages.json", got.URL.Path)
+	user, pass, ok := got.BasicAuth()
+	assert.True(t, ok)
+	assert.Equal(t, "AC123", user)
+	assert.Equal(t, "secret", pass)
+	assert.Equal(t, "+6281234567890", got.PostForm.Get("To"))
+	assert.Equal(t, "+15005550006", got.PostForm.Get("From"))
+	assert.Equal(t, "Your payment is due", got.PostForm.Get("Body"))
+
+	status = http.StatusBadRequest
+	assert.ErrorIs(t, s.Send(context.Background(), msg), ErrRejected)
+
+	for _, status = range []int{http.StatusUnauthorized, http.StatusTooManyRequests, http.StatusServiceUnavailable} {
+		err := s.Send(context.Background(), msg)
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, ErrRejected, "status %d", status)
+	}
+}
+
+func TestNewTwilioSenderRequiresCredentials(t *testing.T) {
+	_, err := NewTwilioSender(TwilioConfig{AccountSID: "AC123", From: "+15005550006"})
+	assert.Error(t, err)
+}
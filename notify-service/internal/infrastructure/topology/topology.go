@@ -0,0 +1,131 @@
+package topology
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"notify-service/internal/config"
+	"slices"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Declarer is the part of *amqp.Channel that declares the topology.
+type Declarer interface {
+	ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args amqp.Table) error
+	QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error)
+	QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error
+}
+
+var exchangeTypes = []string{amqp.ExchangeDirect, amqp.ExchangeFanout, amqp.ExchangeTopic, amqp.ExchangeHeaders}
+
+// Validate checks that names are set and unique, exchange types are known,
+// and every binding and dead-letter exchange refers to a declared exchange.
+func Validate(cfg config.TopologyConfig) error {
+	exchanges := map[string]bool{}
+	for _, e := range cfg.Exchanges {
+		if e.Name == "" {
+			return errors.New("topology exchange name cannot be empty")
+		}
+		if exchanges[e.Name] {
+			return fmt.Errorf("topology exchange %q is declared twice", e.Name)
+		}
+		if e.Type != "" && !slices.Contains(exchangeTypes, e.Type) {
+			return fmt.Errorf("topology exchange %q has unknown type %q", e.Name, e.Type)
+		}
+		exchanges[e.Name] = true
+	}
+
+	queues := map[string]bool{}
+	for _, q := range cfg.Queues {
+		if q.Name == "" {
+			return errors.New("topology queue name cannot be empty")
+		}
+		if queues[q.Name] {
+			return fmt.Errorf("topology queue %q is declared twice", q.Name)
+		}
+		queues[q.Name] = true
+		if q.DeadLetterExchange != "" && !exchanges[q.DeadLetterExchange] {
+			return fmt.Errorf("topology queue %q dead-letters to undeclared exchange %q", q.Name, q.DeadLetterExchange)
+		}
+		if q.DeadLetterRoutingKey != "" && q.DeadLetterExchange == "" {
+			return fmt.Errorf("topology queue %q sets deadLetterRoutingKey without deadLetterExchange", q.Name)
+		}
+		if q.MessageTTL < 0 {
+			return fmt.Errorf("topology queue %q has a negative messageTTL", q.Name)
+		}
+		for _, b := range q.Bindings {
+			if !exchanges[b.Exchange] {
+				return fmt.Errorf("topology queue %q is bound to undeclared exchange %q", q.Name, b.Exchange)
+			}
+			if len(b.RoutingKeys) == 0 {
+				return fmt.Errorf("topology queue %q binding to %q has no routing keys", q.Name, b.Exchange)
+			}
+		}
+	}
+	return nil
+}
+
+// Declare opens a channel on conn and applies cfg.
+func Declare(conn *amqp.Connection, cfg config.TopologyConfig, logger *slog.Logger) error {
+	ch, err := conn.Channel()
+	if err != nil {
+		return fmt.Errorf("failed to open channel for topology declaration: %w", err)
+	}
+	defer ch.Close()
+	return Apply(ch, cfg, logger)
+}
+
+// Apply declares the exchanges, then the queues and their bindings. Every
+// object is durable. Redeclaring an object with the arguments it already has
+// is a no-op; the broker refuses different arguments for an existing queue,
+// so changing a queue's dead-letter or TTL settings means deleting it first
+// or giving it a new name.
+func Apply(ch Declarer, cfg config.TopologyConfig, logger *slog.Logger) error {
+	if err := Validate(cfg); err != nil {
+		return err
+	}
+
+	for _, e := range cfg.Exchanges {
+		kind := e.Type
+		if kind == "" {
+			kind = amqp.ExchangeTopic
+		}
+		if err := ch.ExchangeDeclare(e.Name, kind, true, false, false, false, nil); err != nil {
+			return fmt.Errorf("failed to declare exchange '%s': %w", e.Name, err)
+		}
+		logger.Info("Declared RabbitMQ exchange", "exchange", e.Name, "type", kind)
+	}
+
+	for _, q := range cfg.Queues {
+		if _, err := ch.QueueDeclare(q.Name, true, false, false, false, queueArgs(q)); err != nil {
+			return fmt.Errorf("failed to declare queue '%s': %w", q.Name, err)
+		}
+		for _, b := range q.Bindings {
+			for _, key := range b.RoutingKeys {
+				if err := ch.QueueBind(q.Name, key, b.Exchange, false, nil); err != nil {
+					return fmt.Errorf("failed to bind queue '%s' to '%s' with key '%s': %w", q.Name, b.Exchange, key, err)
+				}
+			}
+		}
+		logger.Info("Declared RabbitMQ queue", "queue", q.Name, "deadLetterExchange", q.DeadLetterExchange, "bindings", len(q.Bindings))
+	}
+	return nil
+}
+
+func queueArgs(q config.QueueConfig) amqp.Table {
+	args := amqp.Table{}
+	if q.DeadLetterExchange != "" {
+		args["x-dead-letter-exchange"] = q.DeadLetterExchange
+	}
+	if q.DeadLetterRoutingKey != "" {
+		args["x-dead-letter-routing-key"] = q.DeadLetterRoutingKey
+	}
+	if q.MessageTTL > 0 {
+		args["x-message-ttl"] = q.MessageTTL.Milliseconds()
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	return args
+}
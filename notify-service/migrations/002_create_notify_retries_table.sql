@@ -0,0 +1,14 @@
+-- migrations/002_create_notify_retries_table.sql
+CREATE TABLE notify_retries (
+    id BIGSERIAL PRIMARY KEY,
+    routing_key VARCHAR(255) NOT NULL,
+    body BYTEA NOT NULL, -- Original message body, decoded again on every attempt
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMPTZ NULL, -- NULL once attempts are exhausted
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- The scheduler only ever looks for due rows
+CREATE INDEX idx_notify_retries_next_attempt_at ON notify_retries (next_attempt_at) WHERE next_attempt_at IS NOT NULL;
@@ -0,0 +1,15 @@
+-- migrations/003_create_notifications_table.sql
+CREATE TABLE notifications (
+    id BIGSERIAL PRIMARY KEY,
+    customer_id BIGINT NOT NULL,
+    event VARCHAR(64) NOT NULL, -- e.g. delinquency_notice, payment_receipt
+    channel VARCHAR(32) NOT NULL,
+    recipient TEXT NOT NULL DEFAULT '',
+    status VARCHAR(16) NOT NULL CHECK (status IN ('SENT', 'FAILED')),
+    error TEXT NOT NULL DEFAULT '',
+    sent_at TIMESTAMPTZ NULL, -- NULL unless status is SENT
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Support looks notifications up per customer, newest first
+CREATE INDEX idx_notifications_customer_created ON notifications (customer_id, created_at DESC);
@@ -0,0 +1,14 @@
+-- migrations/004_add_notification_deferral.sql
+ALTER TABLE notifications
+    ADD COLUMN subject TEXT NOT NULL DEFAULT '',
+    ADD COLUMN body TEXT NOT NULL DEFAULT '', -- Kept so deferred messages can be sent later
+    ADD COLUMN deliver_after TIMESTAMPTZ NULL; -- Set only while status is DEFERRED
+
+ALTER TABLE notifications DROP CONSTRAINT notifications_status_check;
+ALTER TABLE notifications ADD CONSTRAINT notifications_status_check CHECK (status IN ('SENT', 'FAILED', 'DEFERRED'));
+
+-- The dispatcher only ever looks for due deferred rows
+CREATE INDEX idx_notifications_deliver_after ON notifications (deliver_after) WHERE status = 'DEFERRED';
+
+-- Daily caps count what a customer was sent today
+CREATE INDEX idx_notifications_customer_sent_at ON notifications (customer_id, sent_at) WHERE status = 'SENT';
@@ -0,0 +1,9 @@
+-- migrations/005_create_processed_events_table.sql
+CREATE TABLE processed_events (
+    event_id VARCHAR(64) PRIMARY KEY, -- eventId from the message envelope
+    routing_key VARCHAR(255) NOT NULL,
+    processed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Lets old rows be pruned by age
+CREATE INDEX idx_processed_events_processed_at ON processed_events (processed_at);
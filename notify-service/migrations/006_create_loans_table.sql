@@ -0,0 +1,15 @@
+-- migrations/006_create_loans_table.sql
+CREATE TABLE loans (
+    id BIGINT PRIMARY KEY, -- loanId from billing-engine
+    customer_id BIGINT NOT NULL,
+    principal_amount NUMERIC(15, 2) NOT NULL,
+    term_weeks INT NOT NULL,
+    amount_paid NUMERIC(15, 2) NOT NULL DEFAULT 0,
+    status VARCHAR(20) NOT NULL DEFAULT 'ACTIVE', -- ACTIVE, DELINQUENT or PAID_OFF
+    status_changed_at TIMESTAMPTZ NOT NULL, -- Event time of the last status change
+    last_payment_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL, -- Store time provided by event
+    updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_loans_customer_id ON loans (customer_id);
@@ -0,0 +1,13 @@
+-- migrations/007_create_customer_preferences_table.sql
+CREATE TABLE customer_preferences (
+    customer_id BIGINT PRIMARY KEY, -- customerId from billing-engine
+    preferred_channel VARCHAR(32) NOT NULL DEFAULT '', -- Empty when the customer has no preference
+    language VARCHAR(35) NOT NULL DEFAULT '',
+    marketing_opt_out BOOLEAN NOT NULL DEFAULT FALSE,
+    transactional_opt_out BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at TIMESTAMPTZ NOT NULL -- Store time provided by event
+);
+
+-- Messages a customer opted out of are logged as SUPPRESSED instead of sent
+ALTER TABLE notifications DROP CONSTRAINT notifications_status_check;
+ALTER TABLE notifications ADD CONSTRAINT notifications_status_check CHECK (status IN ('SENT', 'FAILED', 'DEFERRED', 'SUPPRESSED'));
@@ -0,0 +1,6 @@
+-- migrations/008_create_customer_contacts_table.sql
+CREATE TABLE customer_contacts (
+    customer_id BIGINT PRIMARY KEY, -- customerId from billing-engine
+    contacts JSONB NOT NULL DEFAULT '[]', -- Every contact of the customer, as the last event listed them
+    updated_at TIMESTAMPTZ NOT NULL -- Store time provided by event
+);
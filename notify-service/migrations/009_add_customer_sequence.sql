@@ -0,0 +1,5 @@
+-- migrations/009_add_customer_sequence.sql
+-- The sequence of the customer.created or customer.updated event the row was
+-- last written from; 0 for rows written from events sent before billing-engine
+-- numbered them. A copy whose sequence is at or below it is older and skipped.
+ALTER TABLE customers ADD COLUMN sequence BIGINT NOT NULL DEFAULT 0;
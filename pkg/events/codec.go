@@ -0,0 +1,92 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+var (
+	// ErrUnknownRoutingKey and ErrMalformed mean the message cannot be
+	// decoded however often it is redelivered.
+	ErrUnknownRoutingKey = errors.New("unknown routing key")
+	ErrMalformed         = errors.New("malformed event")
+)
+
+// registry maps each routing key to a constructor for its event type.
+var registry = map[string]func() Event{
+	RoutingKeyCustomerCreated:                      func() Event { return &CustomerCreatedEvent{} },
+	RoutingKeyCustomerUpdated:                      func() Event { return &CustomerUpdatedEvent{} },
+	RoutingKeyCustomerDelinquencyChanged:           func() Event { return &CustomerDelinquencyChangedEvent{} },
+	RoutingKeyCustomerPreferencesChanged:           func() Event { return &CustomerPreferencesChangedEvent{} },
+	RoutingKeyCustomerRiskScoreRequested:           func() Event { return &CustomerRiskScoreRequestedEvent{} },
+	RoutingKeyCustomerMerged:                       func() Event { return &CustomerMergedEvent{} },
+	RoutingKeyCustomerContactsChanged:              func() Event { return &CustomerContactsChangedEvent{} },
+	RoutingKeyCustomerContactVerificationRequested: func() Event { return &CustomerContactVerificationRequestedEvent{} },
+	RoutingKeyLoanCreated:                          func() Event { return &LoanCreatedEvent{} },
+	RoutingKeyLoanPaymentReceived:                  func() Event { return &LoanPaymentReceivedEvent{} },
+	RoutingKeyLoanDelinquent:                       func() Event { return &LoanDelinquentEvent{} },
+	RoutingKeyLoanPaidOff:                          func() Event { return &LoanPaidOffEvent{} },
+	RoutingKeyLoanReminderDue:                      func() Event { return &LoanReminderDueEvent{} },
+	RoutingKeyCollectionsTaskDue:                   func() Event { return &CollectionsTaskDueEvent{} },
+}
+
+// RoutingKeys returns every known routing key, sorted.
+func RoutingKeys() []string {
+	keys := make([]string, 0, len(registry))
+	for key := range registry {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// IsKnown reports whether routingKey belongs to a registered event.
+func IsKnown(routingKey string) bool {
+	_, ok := registry[routingKey]
+	return ok
+}
+
+// Marshal encodes e as a message body and returns it with the routing key to
+// publish it under.
+func Marshal(e Event) (routingKey string, body []byte, err error) {
+	body, err = json.Marshal(e)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to marshal %s event: %w", e.RoutingKey(), err)
+	}
+	return e.RoutingKey(), body, nil
+}
+
+// Decode returns the event held in a body published under routingKey. The
+// result is a pointer to the registered type, such as *LoanCreatedEvent.
+func Decode(routingKey string, body []byte) (Event, error) {
+	newEvent, ok := registry[routingKey]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownRoutingKey, routingKey)
+	}
+	e := newEvent()
+	if err := json.Unmarshal(body, e); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrMalformed, routingKey, err)
+	}
+	return e, nil
+}
+
+// EventID reads only the event ID of a body, without knowing its type. It is
+// empty for a body that is not a JSON object or has no ID.
+func EventID(body []byte) string {
+	var envelope struct {
+		EventID string `json:"eventId"`
+	}
+	_ = json.Unmarshal(body, &envelope)
+	return envelope.EventID
+}
+
+// Sequence reads only the sequence of a body, zero when it has none.
+func Sequence(body []byte) int64 {
+	var envelope struct {
+		Sequence int64 `json:"sequence"`
+	}
+	_ = json.Unmarshal(body, &envelope)
+	return envelope.Sequence
+}
@@ -0,0 +1,106 @@
+package events
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRoundTrip(t *testing.T) {
+	at := time.Date(2025, 4, 2, 9, 0, 0, 0, time.UTC)
+	loanID := int64(5)
+	principal := 5000000.0
+	for _, e := range []Event{
+		CustomerCreatedEvent{EventID: "e-1", Timestamp: at, Payload: CustomerEventPayload{CustomerID: 1, Name: "Ann", LoanID: &loanID, CreateDate: at, UpdatedAt: at}},
+		CustomerUpdatedEvent{EventID: "e-2", Sequence: 3, Timestamp: at, Payload: CustomerEventPayload{CustomerID: 1, IsDelinquent: true}},
+		CustomerDelinquencyChangedEvent{EventID: "e-3", CustomerID: 1, NewStatus: true, Timestamp: at},
+		CustomerPreferencesChangedEvent{EventID: "e-8", CustomerID: 1, PreferredChannel: "sms", Language: "id", MarketingOptOut: true, UpdatedAt: at, Timestamp: at},
+		CustomerRiskScoreRequestedEvent{EventID: "e-9", CustomerID: 1, PublicID: "7b2f6a6e-1c4d-4f7e-9a35-0d8e2c1b5f44", Name: "Ann", Address: "Jl. Sudirman 1", Reason: "LOAN_APPLICATION", Principal: &principal, Timestamp: at},
+		CustomerMergedEvent{EventID: "e-12", TargetID: 1, SourceID: 2, LoanID: &loanID, MergedAt: at, Timestamp: at},
+		CustomerContactsChangedEvent{EventID: "e-13", CustomerID: 1, Contacts: []ContactPayload{{ContactID: 3, Type: "phone", Value: "+6281234567890", Verified: true, Primary: true}}, UpdatedAt: at, Timestamp: at},
+		CustomerContactVerificationRequestedEvent{EventID: "e-14", CustomerID: 1, ContactID: 4, Type: "email", Value: "ann@example.com", Code: "482913", ExpiresAt: at.Add(15 * time.Minute), Timestamp: at},
+		LoanCreatedEvent{EventID: "e-4", LoanID: 5, CustomerID: 1, PrincipalAmount: 5000000, TermWeeks: 50, Timestamp: at},
+		LoanPaymentReceivedEvent{EventID: "e-5", LoanID: 5, Amount: 110000, Timestamp: at},
+		LoanDelinquentEvent{EventID: "e-6", LoanID: 5, CustomerID: 1, DaysPastDue: 14, Bucket: "1-30", Timestamp: at},
+		LoanPaidOffEvent{EventID: "e-7", LoanID: 5, CustomerID: 1, DocumentID: 2, DocumentURL: "https://storage.example.com/loans/5/certificate.pdf", Timestamp: at},
+		LoanReminderDueEvent{EventID: "e-10", LoanID: 5, CustomerID: 1, DaysPastDue: 8, Step: 7, Channel: "email", Timestamp: at},
+		CollectionsTaskDueEvent{EventID: "e-11", LoanID: 5, CustomerID: 1, DaysPastDue: 14, Step: 14, Task: "CALL", Collector: "rina", Timestamp: at},
+	} {
+		t.Run(e.RoutingKey(), func(t *testing.T) {
+			key, body, err := Marshal(e)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if key != e.RoutingKey() {
+				t.Errorf("routing key = %q, want %q", key, e.RoutingKey())
+			}
+
+			got, err := Decode(key, body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v := reflect.ValueOf(got).Elem().Interface(); !reflect.DeepEqual(v, e) {
+				t.Errorf("decoded %+v, want %+v", v, e)
+			}
+			if id := EventID(body); id != reflect.ValueOf(e).FieldByName("EventID").String() {
+				t.Errorf("EventID = %q", id)
+			}
+		})
+	}
+}
+
+func TestRoutingKeysCoverRegistry(t *testing.T) {
+	keys := RoutingKeys()
+	if len(keys) != 14 {
+		t.Fatalf("got %d routing keys: %v", len(keys), keys)
+	}
+	for _, key := range keys {
+		if !IsKnown(key) {
+			t.Errorf("%s is listed but not known", key)
+		}
+		e, err := Decode(key, []byte(`{}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.RoutingKey() != key {
+			t.Errorf("%s decodes to a %s event", key, e.RoutingKey())
+		}
+	}
+}
+
+func TestWithSequence(t *testing.T) {
+	for key, newEvent := range registry {
+		e, ok := newEvent().(Sequenced)
+		if !ok {
+			continue
+		}
+		t.Run(key, func(t *testing.T) {
+			_, body, err := Marshal(e.WithSequence(4))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if seq := Sequence(body); seq != 4 {
+				t.Errorf("Sequence = %d, want 4", seq)
+			}
+			if _, body, _ := Marshal(e); Sequence(body) != 0 {
+				t.Error("WithSequence changed the event it was called on")
+			}
+		})
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	if _, err := Decode("loan.refinanced", []byte(`{}`)); !errors.Is(err, ErrUnknownRoutingKey) {
+		t.Errorf("unknown routing key: got %v", err)
+	}
+	if _, err := Decode(RoutingKeyLoanCreated, []byte(`{"loanId":"seven"}`)); !errors.Is(err, ErrMalformed) {
+		t.Errorf("malformed body: got %v", err)
+	}
+	if id := EventID([]byte(`not json`)); id != "" {
+		t.Errorf("EventID of a non-JSON body = %q", id)
+	}
+	if seq := Sequence([]byte(`not json`)); seq != 0 {
+		t.Errorf("Sequence of a non-JSON body = %d", seq)
+	}
+}
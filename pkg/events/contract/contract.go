@@ -0,0 +1,158 @@
+// Package contract pins the bodies notify-service consumes. Each routing key
+// notify-service subscribes to has an example body in bodies/, written as
+// notify-service expects it on the wire. billing-engine's tests check that
+// what it publishes has the same shape, and notify-service's tests that its
+// handlers accept every example, so a change to either side that the other
+// cannot take fails a test before the two are deployed apart.
+//
+// Changing an example is changing the contract: a field may be added to an
+// example once notify-service ignores or handles it, and removed or renamed
+// only once no deployed notify-service reads it.
+package contract
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const eventIDField = "eventId"
+
+//go:embed bodies/*.json
+var bodies embed.FS
+
+// RoutingKeys returns the routing keys that have an example body, sorted.
+func RoutingKeys() []string {
+	entries, err := bodies.ReadDir("bodies")
+	if err != nil {
+		panic(err)
+	}
+	keys := make([]string, len(entries))
+	for i, e := range entries {
+		keys[i] = strings.TrimSuffix(e.Name(), ".json")
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// Body returns the example body of routingKey, or false when the contract
+// has none.
+func Body(routingKey string) ([]byte, bool) {
+	body, err := bodies.ReadFile("bodies/" + routingKey + ".json")
+	if err != nil {
+		return nil, false
+	}
+	return body, true
+}
+
+// Match reports how body, published under routingKey, differs from the
+// example. The values may differ but the shape may not: body must have
+// exactly the example's fields, at any depth, each holding the same JSON
+// type, with timestamps in RFC 3339. Its event ID must be set, since
+// consumers deduplicate on it. Optional fields are in the examples, so a
+// body being matched must set them too.
+func Match(routingKey string, body []byte) error {
+	example, ok := Body(routingKey)
+	if !ok {
+		return fmt.Errorf("no contract for routing key %s", routingKey)
+	}
+	var want, got any
+	if err := decode(example, &want); err != nil {
+		return fmt.Errorf("contract for %s: %w", routingKey, err)
+	}
+	if err := decode(body, &got); err != nil {
+		return fmt.Errorf("%s body: %w", routingKey, err)
+	}
+
+	var problems []error
+	if object, ok := got.(map[string]any); ok {
+		if id, _ := object[eventIDField].(string); id == "" {
+			problems = append(problems, fmt.Errorf("%s: must be set", eventIDField))
+		}
+	}
+	compare("", want, got, &problems)
+	if len(problems) > 0 {
+		return fmt.Errorf("%s body does not match the contract: %w", routingKey, errors.Join(problems...))
+	}
+	return nil
+}
+
+func decode(data []byte, v *any) error {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	return d.Decode(v)
+}
+
+func compare(path string, want, got any, problems *[]error) {
+	if kind(want) != kind(got) {
+		*problems = append(*problems, fmt.Errorf("%s: is %s, the contract has %s", field(path), kind(got), kind(want)))
+		return
+	}
+	switch want := want.(type) {
+	case map[string]any:
+		got := got.(map[string]any)
+		for _, name := range sortedKeys(want) {
+			if _, ok := got[name]; !ok {
+				*problems = append(*problems, fmt.Errorf("%s: missing", field(path+"."+name)))
+				continue
+			}
+			compare(path+"."+name, want[name], got[name], problems)
+		}
+		for _, name := range sortedKeys(got) {
+			if _, ok := want[name]; !ok {
+				*problems = append(*problems, fmt.Errorf("%s: not in the contract", field(path+"."+name)))
+			}
+		}
+	case []any:
+		got := got.([]any)
+		if len(want) > 0 {
+			for i, item := range got {
+				compare(fmt.Sprintf("%s[%d]", path, i), want[0], item, problems)
+			}
+		}
+	case string:
+		if _, err := time.Parse(time.RFC3339, want); err == nil {
+			if _, err := time.Parse(time.RFC3339, got.(string)); err != nil {
+				*problems = append(*problems, fmt.Errorf("%s: %q is not an RFC 3339 timestamp", field(path), got))
+			}
+		}
+	}
+}
+
+func kind(v any) string {
+	switch v.(type) {
+	case map[string]any:
+		return "an object"
+	case []any:
+		return "an array"
+	case string:
+		return "a string"
+	case json.Number:
+		return "a number"
+	case bool:
+		return "a boolean"
+	default:
+		return "null"
+	}
+}
+
+func field(path string) string {
+	if path == "" {
+		return "body"
+	}
+	return strings.TrimPrefix(path, ".")
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
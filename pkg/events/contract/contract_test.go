@@ -0,0 +1,62 @@
+package contract
+
+import (
+	"events"
+	"strings"
+	"testing"
+)
+
+// TestExamplesMatchEvents decodes every example into its event and encodes it
+// again. A field the event type lost, renamed or retyped comes back missing
+// or different.
+func TestExamplesMatchEvents(t *testing.T) {
+	for _, key := range RoutingKeys() {
+		t.Run(key, func(t *testing.T) {
+			if !events.IsKnown(key) {
+				t.Fatalf("%s is not a registered routing key", key)
+			}
+			body, _ := Body(key)
+			decoded, err := events.Decode(key, body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, encoded, err := events.Marshal(decoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := Match(key, encoded); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	const key = events.RoutingKeyCustomerCreated
+	valid := `{"eventId":"e-1","sequence":2,"timestamp":"2025-04-02T09:00:00+07:00","payload":{"customerId":1,"name":"Ann","address":"","isDelinquent":true,"active":false,"loanId":9,"createDate":"2025-04-02T09:00:00Z","updatedAt":"2025-04-02T09:00:00Z"}}`
+	if err := Match(key, []byte(valid)); err != nil {
+		t.Fatalf("other values of the same shape: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name, body, want string
+	}{
+		{"missing event ID", strings.Replace(valid, `"e-1"`, `""`, 1), "eventId: must be set"},
+		{"missing field", strings.Replace(valid, `"loanId":9,`, ``, 1), "payload.loanId: missing"},
+		{"extra field", strings.Replace(valid, `"active":false`, `"active":false,"status":"ACTIVE"`, 1), "payload.status: not in the contract"},
+		{"retyped field", strings.Replace(valid, `"customerId":1`, `"customerId":"1"`, 1), "payload.customerId: is a string, the contract has a number"},
+		{"null field", strings.Replace(valid, `"loanId":9`, `"loanId":null`, 1), "payload.loanId: is null"},
+		{"timestamp format", strings.Replace(valid, `"2025-04-02T09:00:00+07:00"`, `"2025-04-02 09:00"`, 1), "timestamp: \"2025-04-02 09:00\" is not an RFC 3339 timestamp"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Match(key, []byte(tc.body))
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("Match() = %v, want an error containing %q", err, tc.want)
+			}
+		})
+	}
+
+	if err := Match("loan.unknown", []byte(valid)); err == nil {
+		t.Error("Match() of a routing key without a contract succeeded")
+	}
+}
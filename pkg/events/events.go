@@ -0,0 +1,288 @@
+// Package events defines the messages billing-engine publishes and
+// notify-service consumes: their routing keys, their JSON bodies and the
+// helpers that encode and decode them. Both services build against this
+// module, so a field added or renamed here changes producer and consumer
+// together.
+//
+// Every event carries its event ID at the top level. Consumers use it to
+// recognise redelivered messages; messages from publishers that predate
+// event IDs leave it empty.
+//
+// Events about a customer also carry a sequence next to the event ID. It
+// numbers that customer's events from 1, in the order billing-engine raised
+// them; customer.merged is numbered among the target's events. A consumer that gets a
+// sequence more than one past the last it handled for the customer has either
+// missed an event or received it early, and can wait for or replay the ones
+// in between. The sequence is zero, and left out, on events raised before
+// sequences and on events billing-engine could not number.
+package events
+
+import "time"
+
+const (
+	RoutingKeyCustomerCreated            = "customer.created"
+	RoutingKeyCustomerUpdated            = "customer.updated"
+	RoutingKeyCustomerDelinquencyChanged = "customer.delinquency.changed"
+	RoutingKeyCustomerPreferencesChanged = "customer.preferences.changed"
+	RoutingKeyCustomerRiskScoreRequested = "customer.risk_score.requested"
+	RoutingKeyCustomerMerged             = "customer.merged"
+	RoutingKeyCustomerContactsChanged    = "customer.contacts.changed"
+
+	RoutingKeyCustomerContactVerificationRequested = "customer.contact.verification_requested"
+
+	RoutingKeyLoanCreated         = "loan.created"
+	RoutingKeyLoanPaymentReceived = "loan.payment.received"
+	RoutingKeyLoanDelinquent      = "loan.delinquent"
+	RoutingKeyLoanPaidOff         = "loan.paid_off"
+	RoutingKeyLoanReminderDue     = "loan.reminder.due"
+
+	RoutingKeyCollectionsTaskDue = "collections.task.due"
+)
+
+// Event is a message body. Its routing key is also the event's type.
+type Event interface {
+	RoutingKey() string
+}
+
+// Sequenced is an event about a customer that can carry its sequence.
+type Sequenced interface {
+	Event
+	// WithSequence returns a copy of the event numbered seq.
+	WithSequence(seq int64) Event
+}
+
+type CustomerEventPayload struct {
+	CustomerID   int64     `json:"customerId"`
+	Name         string    `json:"name"`
+	Address      string    `json:"address"`
+	IsDelinquent bool      `json:"isDelinquent"`
+	Active       bool      `json:"active"`
+	LoanID       *int64    `json:"loanId,omitempty"`
+	CreateDate   time.Time `json:"createDate"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+type CustomerCreatedEvent struct {
+	EventID   string               `json:"eventId"`
+	Sequence  int64                `json:"sequence,omitempty"`
+	Timestamp time.Time            `json:"timestamp"`
+	Payload   CustomerEventPayload `json:"payload"`
+}
+
+type CustomerUpdatedEvent struct {
+	EventID   string               `json:"eventId"`
+	Sequence  int64                `json:"sequence,omitempty"`
+	Timestamp time.Time            `json:"timestamp"`
+	Payload   CustomerEventPayload `json:"payload"`
+}
+
+// CustomerDelinquencyChangedEvent is published when the delinquency flag of a
+// customer flips. It carries no payload envelope.
+type CustomerDelinquencyChangedEvent struct {
+	EventID    string    `json:"eventId"`
+	Sequence   int64     `json:"sequence,omitempty"`
+	CustomerID int64     `json:"customerId"`
+	LoanID     *int64    `json:"loanId,omitempty"`
+	NewStatus  bool      `json:"newStatus"`
+	OldStatus  bool      `json:"oldStatus"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// CustomerPreferencesChangedEvent carries a customer's communication
+// preferences in full. PreferredChannel and Language are empty when the
+// customer has no preference. UpdatedAt orders the changes, so consumers can
+// ignore a copy older than the one they hold.
+type CustomerPreferencesChangedEvent struct {
+	EventID             string    `json:"eventId"`
+	Sequence            int64     `json:"sequence,omitempty"`
+	CustomerID          int64     `json:"customerId"`
+	PreferredChannel    string    `json:"preferredChannel,omitempty"`
+	Language            string    `json:"language,omitempty"`
+	MarketingOptOut     bool      `json:"marketingOptOut"`
+	TransactionalOptOut bool      `json:"transactionalOptOut"`
+	UpdatedAt           time.Time `json:"updatedAt"`
+	Timestamp           time.Time `json:"timestamp"`
+}
+
+// CustomerRiskScoreRequestedEvent asks the credit bureau integration to
+// score a customer. billing-engine does not wait for the answer: the
+// integration stores the score it gets back through the customer API.
+// Reason is CUSTOMER_CREATED or LOAN_APPLICATION; Principal is the amount
+// applied for and is only set for the latter.
+type CustomerRiskScoreRequestedEvent struct {
+	EventID     string    `json:"eventId"`
+	Sequence    int64     `json:"sequence,omitempty"`
+	CustomerID  int64     `json:"customerId"`
+	PublicID    string    `json:"publicId,omitempty"`
+	ExternalRef *string   `json:"externalRef,omitempty"`
+	Name        string    `json:"name"`
+	Address     string    `json:"address"`
+	Reason      string    `json:"reason"`
+	Principal   *float64  `json:"principal,omitempty"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// CustomerMergedEvent is published when a duplicate customer, SourceID, was
+// merged into TargetID. LoanID is the loan that moved to the target and is
+// omitted when the source held none. The source is inactive from then on; a
+// customer.updated event for each customer follows in the same batch.
+type CustomerMergedEvent struct {
+	EventID   string    `json:"eventId"`
+	Sequence  int64     `json:"sequence,omitempty"`
+	TargetID  int64     `json:"targetId"`
+	SourceID  int64     `json:"sourceId"`
+	LoanID    *int64    `json:"loanId,omitempty"`
+	MergedAt  time.Time `json:"mergedAt"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// ContactPayload is one of a customer's contacts. Type is "phone" or
+// "email", and Value a phone number in E.164 form or an email address.
+type ContactPayload struct {
+	ContactID int64  `json:"contactId"`
+	Type      string `json:"type"`
+	Value     string `json:"value"`
+	Verified  bool   `json:"verified"`
+	Primary   bool   `json:"primary"`
+}
+
+// CustomerContactsChangedEvent carries every contact of a customer, verified
+// or not, after one was added, changed, verified or removed. Contacts is an
+// empty array for a customer who has none left. UpdatedAt orders the
+// changes, so consumers can ignore a copy older than the one they hold.
+type CustomerContactsChangedEvent struct {
+	EventID    string           `json:"eventId"`
+	Sequence   int64            `json:"sequence,omitempty"`
+	CustomerID int64            `json:"customerId"`
+	Contacts   []ContactPayload `json:"contacts"`
+	UpdatedAt  time.Time        `json:"updatedAt"`
+	Timestamp  time.Time        `json:"timestamp"`
+}
+
+// CustomerContactVerificationRequestedEvent asks for Code to be sent to the
+// contact Value, which is not verified yet, so the customer can confirm they
+// receive messages there. The code is only good until ExpiresAt; billing-engine
+// keeps a hash of it and does not record this event in its event log.
+type CustomerContactVerificationRequestedEvent struct {
+	EventID    string    `json:"eventId"`
+	CustomerID int64     `json:"customerId"`
+	ContactID  int64     `json:"contactId"`
+	Type       string    `json:"type"`
+	Value      string    `json:"value"`
+	Code       string    `json:"code"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+type LoanCreatedEvent struct {
+	EventID         string    `json:"eventId"`
+	LoanID          int64     `json:"loanId"`
+	CustomerID      int64     `json:"customerId"`
+	PrincipalAmount float64   `json:"principalAmount"`
+	TermWeeks       int       `json:"termWeeks"`
+	Timestamp       time.Time `json:"timestamp"`
+}
+
+// LoanPaymentReceivedEvent names only the loan; consumers look the customer
+// up in their own read model.
+type LoanPaymentReceivedEvent struct {
+	EventID   string    `json:"eventId"`
+	LoanID    int64     `json:"loanId"`
+	Amount    float64   `json:"amount"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// LoanDelinquentEvent asks for a delinquency notice to the customer of the
+// loan. It is raised once when the customer is flagged delinquent and once
+// each time the loan's days past due move into another Bucket, one of
+// "1-30", "31-60", "61-90" or "90+", while they stay flagged.
+type LoanDelinquentEvent struct {
+	EventID     string    `json:"eventId"`
+	Sequence    int64     `json:"sequence,omitempty"`
+	LoanID      int64     `json:"loanId"`
+	CustomerID  int64     `json:"customerId"`
+	DaysPastDue int       `json:"daysPastDue"`
+	Bucket      string    `json:"bucket,omitempty"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// LoanReminderDueEvent asks for a payment reminder to the customer of a
+// past-due loan on Channel, "sms" or "email". Step is the days past due of
+// the escalation step that raised it, so a consumer can tell the first
+// reminder from a later one.
+type LoanReminderDueEvent struct {
+	EventID     string    `json:"eventId"`
+	Sequence    int64     `json:"sequence,omitempty"`
+	LoanID      int64     `json:"loanId"`
+	CustomerID  int64     `json:"customerId"`
+	DaysPastDue int       `json:"daysPastDue"`
+	Step        int       `json:"step"`
+	Channel     string    `json:"channel"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// LoanPaidOffEvent announces that a loan was repaid in full, once its payoff
+// certificate has been issued. DocumentID is the certificate, and
+// DocumentURL a link the customer can download it from for a limited time;
+// it is empty when no link could be made.
+type LoanPaidOffEvent struct {
+	EventID     string    `json:"eventId"`
+	Sequence    int64     `json:"sequence,omitempty"`
+	LoanID      int64     `json:"loanId"`
+	CustomerID  int64     `json:"customerId"`
+	DocumentID  int64     `json:"documentId,omitempty"`
+	DocumentURL string    `json:"documentUrl,omitempty"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// CollectionsTaskDueEvent asks the collections team to act on a past-due
+// loan. Task is CALL or LETTER. Collector is the collector whose queue the
+// loan is in, and is empty while no rule has assigned it.
+type CollectionsTaskDueEvent struct {
+	EventID     string    `json:"eventId"`
+	Sequence    int64     `json:"sequence,omitempty"`
+	LoanID      int64     `json:"loanId"`
+	CustomerID  int64     `json:"customerId"`
+	DaysPastDue int       `json:"daysPastDue"`
+	Step        int       `json:"step"`
+	Task        string    `json:"task"`
+	Collector   string    `json:"collector,omitempty"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+func (CustomerCreatedEvent) RoutingKey() string { return RoutingKeyCustomerCreated }
+func (CustomerUpdatedEvent) RoutingKey() string { return RoutingKeyCustomerUpdated }
+func (CustomerDelinquencyChangedEvent) RoutingKey() string {
+	return RoutingKeyCustomerDelinquencyChanged
+}
+func (CustomerPreferencesChangedEvent) RoutingKey() string {
+	return RoutingKeyCustomerPreferencesChanged
+}
+func (CustomerRiskScoreRequestedEvent) RoutingKey() string {
+	return RoutingKeyCustomerRiskScoreRequested
+}
+func (CustomerMergedEvent) RoutingKey() string { return RoutingKeyCustomerMerged }
+func (CustomerContactsChangedEvent) RoutingKey() string {
+	return RoutingKeyCustomerContactsChanged
+}
+func (CustomerContactVerificationRequestedEvent) RoutingKey() string {
+	return RoutingKeyCustomerContactVerificationRequested
+}
+func (LoanCreatedEvent) RoutingKey() string         { return RoutingKeyLoanCreated }
+func (LoanPaymentReceivedEvent) RoutingKey() string { return RoutingKeyLoanPaymentReceived }
+func (LoanDelinquentEvent) RoutingKey() string      { return RoutingKeyLoanDelinquent }
+func (LoanPaidOffEvent) RoutingKey() string         { return RoutingKeyLoanPaidOff }
+func (LoanReminderDueEvent) RoutingKey() string     { return RoutingKeyLoanReminderDue }
+func (CollectionsTaskDueEvent) RoutingKey() string  { return RoutingKeyCollectionsTaskDue }
+
+func (e CustomerCreatedEvent) WithSequence(seq int64) Event            { e.Sequence = seq; return e }
+func (e CustomerUpdatedEvent) WithSequence(seq int64) Event            { e.Sequence = seq; return e }
+func (e CustomerDelinquencyChangedEvent) WithSequence(seq int64) Event { e.Sequence = seq; return e }
+func (e CustomerPreferencesChangedEvent) WithSequence(seq int64) Event { e.Sequence = seq; return e }
+func (e CustomerRiskScoreRequestedEvent) WithSequence(seq int64) Event { e.Sequence = seq; return e }
+func (e CustomerMergedEvent) WithSequence(seq int64) Event             { e.Sequence = seq; return e }
+func (e CustomerContactsChangedEvent) WithSequence(seq int64) Event    { e.Sequence = seq; return e }
+func (e LoanDelinquentEvent) WithSequence(seq int64) Event             { e.Sequence = seq; return e }
+func (e LoanPaidOffEvent) WithSequence(seq int64) Event                { e.Sequence = seq; return e }
+func (e LoanReminderDueEvent) WithSequence(seq int64) Event            { e.Sequence = seq; return e }
+func (e CollectionsTaskDueEvent) WithSequence(seq int64) Event         { e.Sequence = seq; return e }